@@ -1,3 +1,6 @@
+//go:build auth_example
+// +build auth_example
+
 package main
 
 import (
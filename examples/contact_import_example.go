@@ -1,3 +1,6 @@
+//go:build contact_import_example
+// +build contact_import_example
+
 package main
 
 import (
@@ -1,3 +1,6 @@
+//go:build csv_to_excel_conversion
+// +build csv_to_excel_conversion
+
 package main
 
 import (
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+// TestLeadStatusMigrationBackfill runs the lead status migration over a
+// temporary leads table and checks the status each kind of lead is given
+func TestLeadStatusMigrationBackfill(t *testing.T) {
+	defer ResetInstance()
+	srv := New()
+	ctx := context.Background()
+
+	migration, err := migrationFiles.ReadFile("migrations/20250201000002_lead_status.sql")
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+
+	// Temporary tables live on one connection and shadow public.leads there
+	conn, err := srv.GetDB().Conn(ctx)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE leads (
+			name varchar(50) PRIMARY KEY,
+			organization_id uuid,
+			user_id uuid,
+			won_status varchar(20),
+			active boolean NOT NULL,
+			created_at timestamptz NOT NULL,
+			date_last_stage_update timestamptz,
+			deleted_at timestamptz
+		);
+		INSERT INTO leads (name, user_id, won_status, active, created_at, date_last_stage_update) VALUES
+			('won', NULL, 'won', false, '2025-01-01', NULL),
+			('lost', NULL, 'lost', true, '2025-01-01', '2025-01-05'),
+			('inactive', NULL, 'pending', false, '2025-01-01', '2025-01-05'),
+			('moved', '00000000-0000-0000-0000-000000000001', 'pending', true, '2025-01-01', '2025-01-05'),
+			('untouched', NULL, 'pending', true, '2025-01-01', '2025-01-01'),
+			('fresh', NULL, NULL, true, '2025-01-01', NULL)`)
+	if err != nil {
+		t.Fatalf("create leads: %v", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, string(migration)); err != nil {
+		t.Fatalf("run migration: %v", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT name, status, assigned_to IS NOT DISTINCT FROM user_id FROM leads`)
+	if err != nil {
+		t.Fatalf("read leads: %v", err)
+	}
+	defer rows.Close()
+
+	want := map[string]string{
+		"won":       "won",
+		"lost":      "lost",
+		"inactive":  "archived",
+		"moved":     "in_progress",
+		"untouched": "new",
+		"fresh":     "new",
+	}
+	got := map[string]string{}
+	for rows.Next() {
+		var name, status string
+		var assignedToUser bool
+		if err := rows.Scan(&name, &status, &assignedToUser); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if !assignedToUser {
+			t.Errorf("lead %s: expected assigned_to to be backfilled from user_id", name)
+		}
+		got[name] = status
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read leads: %v", err)
+	}
+
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("lead %s: expected status %q, got %q", name, status, got[name])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles are the migrations the binary was built with, so the
+// startup self-check knows which schema version the code expects
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// LatestMigrationVersion returns the version of the newest migration built
+// into the binary, read from the numeric prefix of its file name
+func LatestMigrationVersion() (uint, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "migrations/"), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no numeric version", name)
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations are built in")
+	}
+	return latest, nil
+}
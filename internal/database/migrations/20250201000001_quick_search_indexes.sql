@@ -0,0 +1,44 @@
+-- Migration: Quick Search Indexes
+-- Description: Trigram indexes backing the command palette "jump to record" search
+-- Version: 20250201000001
+
+-- ============================================================================
+-- Trigram Indexes
+-- ============================================================================
+-- pg_trgm lives in the extensions schema (see 20250101000035). Recreating the
+-- extension there dropped the original name indexes, so they are rebuilt here
+-- alongside indexes for record numbers and references.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA extensions;
+
+-- Contacts
+CREATE INDEX IF NOT EXISTS idx_contacts_name_trgm ON contacts USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_contacts_email_trgm ON contacts USING gin(email extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- Leads
+CREATE INDEX IF NOT EXISTS idx_leads_name_trgm ON leads USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_leads_email_trgm ON leads USING gin(email extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- Products
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_products_default_code_trgm ON products USING gin(default_code extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- Sales orders
+CREATE INDEX IF NOT EXISTS idx_sales_orders_name_trgm ON sales_orders USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- Invoices
+CREATE INDEX IF NOT EXISTS idx_invoices_name_trgm ON invoices USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- Purchase orders
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_name_trgm ON purchase_orders USING gin(name extensions.gin_trgm_ops) WHERE deleted_at IS NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'search', 'read'),
+    ('p', 'role:accountant', 'search', 'read'),
+    ('p', 'role:sales', 'search', 'read'),
+    ('p', 'role:viewer', 'search', 'read')
+ON CONFLICT DO NOTHING;
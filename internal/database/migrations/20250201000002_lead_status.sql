@@ -0,0 +1,38 @@
+-- Migration: Lead Lifecycle Status
+-- Description: Adds an explicit lead lifecycle status and the assigned_to column used by assignment rules
+-- Version: 20250201000002
+
+-- ============================================================================
+-- Columns
+-- ============================================================================
+
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'new';
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS assigned_to uuid;
+
+-- ============================================================================
+-- Backfill
+-- ============================================================================
+-- Status was previously inferred from won_status and active. Closed outcomes
+-- take precedence over the active flag; open leads that already progressed
+-- past their first stage are considered in progress.
+
+UPDATE leads SET status = CASE
+    WHEN won_status = 'won' THEN 'won'
+    WHEN won_status = 'lost' THEN 'lost'
+    WHEN active = false THEN 'archived'
+    WHEN date_last_stage_update IS NOT NULL AND date_last_stage_update > created_at THEN 'in_progress'
+    ELSE 'new'
+END;
+
+UPDATE leads SET assigned_to = user_id WHERE assigned_to IS NULL AND user_id IS NOT NULL;
+
+-- ============================================================================
+-- Constraints & Indexes
+-- ============================================================================
+
+ALTER TABLE leads DROP CONSTRAINT IF EXISTS leads_status_check;
+ALTER TABLE leads ADD CONSTRAINT leads_status_check
+    CHECK (status IN ('new', 'in_progress', 'won', 'lost', 'archived'));
+
+CREATE INDEX IF NOT EXISTS idx_leads_org_status ON leads(organization_id, status) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_leads_assigned_to ON leads(assigned_to) WHERE deleted_at IS NULL;
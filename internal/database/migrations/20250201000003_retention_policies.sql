@@ -0,0 +1,86 @@
+-- Migration: Data Retention Policies
+-- Description: Per-organization retention policies, legal holds and enforcement run history
+-- Version: 20250201000003
+
+-- ============================================================================
+-- Retention Policies
+-- ============================================================================
+-- Rows only exist for organizations that override the defaults defined in the
+-- retention module (tracking events 90 days, audit logs 7 years, lost leads 3 years).
+
+CREATE TABLE IF NOT EXISTS retention_policies (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity_class varchar(50) NOT NULL,
+    retention_days integer NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+    CONSTRAINT retention_policies_org_class_unique UNIQUE (organization_id, entity_class),
+    CONSTRAINT retention_policies_entity_class_check CHECK (
+        entity_class IN ('tracking_events', 'audit_logs', 'lost_leads')
+    ),
+    CONSTRAINT retention_policies_days_check CHECK (retention_days > 0)
+);
+
+-- ============================================================================
+-- Legal Holds
+-- ============================================================================
+-- A hold without a record_id exempts the whole entity class.
+
+CREATE TABLE IF NOT EXISTS retention_legal_holds (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity_class varchar(50) NOT NULL,
+    record_id uuid,
+    reason text NOT NULL,
+    placed_by uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    released_at timestamptz,
+    released_by uuid,
+    CONSTRAINT retention_legal_holds_entity_class_check CHECK (
+        entity_class IN ('tracking_events', 'audit_logs', 'lost_leads')
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_legal_holds_active
+    ON retention_legal_holds(organization_id, entity_class, record_id)
+    WHERE released_at IS NULL;
+
+-- ============================================================================
+-- Enforcement Runs
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity_class varchar(50) NOT NULL,
+    cutoff timestamptz NOT NULL,
+    purged_count integer NOT NULL DEFAULT 0,
+    held_count integer NOT NULL DEFAULT 0,
+    status varchar(20) NOT NULL,
+    error text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    CONSTRAINT retention_runs_status_check CHECK (status IN ('completed', 'skipped', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_org_started ON retention_runs(organization_id, started_at DESC);
+
+-- ============================================================================
+-- Purge Indexes
+-- ============================================================================
+
+CREATE INDEX IF NOT EXISTS idx_delivery_tracking_events_org_time ON delivery_tracking_events(organization_id, event_time);
+CREATE INDEX IF NOT EXISTS idx_permission_audit_log_org_created ON permission_audit_log(organization_id, created_at);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'retention', 'read'),
+    ('p', 'role:admin', 'retention', 'manage'),
+    ('p', 'role:accountant', 'retention', 'read')
+ON CONFLICT DO NOTHING;
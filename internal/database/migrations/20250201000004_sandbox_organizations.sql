@@ -0,0 +1,36 @@
+-- Migration: Sandbox Organizations
+-- Description: Paired sandbox organizations for integrators testing against isolated data
+-- Version: 20250201000004
+
+-- ============================================================================
+-- Organization Sandbox Flag
+-- ============================================================================
+-- A sandbox is a regular organization row flagged is_sandbox and paired with
+-- the production organization that created it. All data isolation follows from
+-- the existing organization_id scoping.
+
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS is_sandbox boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS sandbox_parent_id uuid REFERENCES organizations(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS sandbox_last_reset_at timestamptz;
+
+ALTER TABLE organizations
+    ADD CONSTRAINT organizations_sandbox_parent_check CHECK (
+        (is_sandbox AND sandbox_parent_id IS NOT NULL) OR (NOT is_sandbox AND sandbox_parent_id IS NULL)
+    );
+
+-- One sandbox per production organization
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_sandbox_parent
+    ON organizations(sandbox_parent_id)
+    WHERE sandbox_parent_id IS NOT NULL AND deleted_at IS NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'sandbox', 'read'),
+    ('p', 'role:admin', 'sandbox', 'manage'),
+    ('p', 'role:accountant', 'sandbox', 'read'),
+    ('p', 'role:sales', 'sandbox', 'read')
+ON CONFLICT DO NOTHING;
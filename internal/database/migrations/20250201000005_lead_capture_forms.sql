@@ -0,0 +1,46 @@
+-- Migration: Lead Capture Forms
+-- Description: Web capture forms with a per-form dedup window and merge strategy for resubmissions
+-- Version: 20250201000005
+
+-- ============================================================================
+-- Lead Capture Forms
+-- ============================================================================
+-- A submission whose email matches an open lead created inside the form's
+-- dedup window is handled by merge_strategy instead of creating a new lead.
+
+CREATE TABLE IF NOT EXISTS lead_capture_forms (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    source_id uuid REFERENCES lead_sources(id) ON DELETE SET NULL,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE SET NULL,
+    dedup_window_minutes integer NOT NULL DEFAULT 1440,
+    merge_strategy varchar(20) NOT NULL DEFAULT 'update_existing',
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT lead_capture_forms_dedup_window_check CHECK (dedup_window_minutes BETWEEN 0 AND 43200),
+    CONSTRAINT lead_capture_forms_merge_strategy_check CHECK (merge_strategy IN ('create_new', 'update_existing', 'keep_existing'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_capture_forms_org ON lead_capture_forms(organization_id);
+
+-- Supports the open-lead-by-email lookup made on every capture submission
+CREATE INDEX IF NOT EXISTS idx_leads_open_email
+    ON leads(organization_id, lower(email), created_at DESC)
+    WHERE status IN ('new', 'in_progress') AND deleted_at IS NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'lead_capture_forms', 'read'),
+    ('p', 'role:admin', 'lead_capture_forms', 'create'),
+    ('p', 'role:admin', 'lead_capture_forms', 'update'),
+    ('p', 'role:admin', 'lead_capture_forms', 'delete'),
+    ('p', 'role:admin', 'lead_capture_forms', 'submit'),
+    ('p', 'role:sales', 'lead_capture_forms', 'read'),
+    ('p', 'role:sales', 'lead_capture_forms', 'submit')
+ON CONFLICT DO NOTHING;
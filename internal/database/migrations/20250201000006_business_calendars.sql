@@ -0,0 +1,70 @@
+-- Migration: Business Calendars
+-- Description: Per-organization and per-team business hours with shared holiday sets
+-- Version: 20250201000006
+
+-- ============================================================================
+-- Holiday Sets
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS business_holiday_sets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_holiday_sets_org ON business_holiday_sets(organization_id);
+
+CREATE TABLE IF NOT EXISTS business_holidays (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    holiday_set_id uuid NOT NULL REFERENCES business_holiday_sets(id) ON DELETE CASCADE,
+    holiday_date date NOT NULL,
+    name varchar(255) NOT NULL DEFAULT '',
+    CONSTRAINT business_holidays_set_date_unique UNIQUE (holiday_set_id, holiday_date)
+);
+
+-- ============================================================================
+-- Business Calendars
+-- ============================================================================
+-- weekly_hours maps lowercase weekday names to lists of {"start": "HH:MM", "end": "HH:MM"}.
+-- A team calendar overrides the organization default (team_id IS NULL AND is_default)
+-- for that team; organizations without a default are treated as always open.
+
+CREATE TABLE IF NOT EXISTS business_calendars (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    timezone varchar(64) NOT NULL DEFAULT 'UTC',
+    weekly_hours jsonb NOT NULL,
+    holiday_set_id uuid REFERENCES business_holiday_sets(id) ON DELETE SET NULL,
+    is_default boolean NOT NULL DEFAULT false,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT business_calendars_team_default_check CHECK (team_id IS NULL OR is_default = false)
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_calendars_org ON business_calendars(organization_id);
+
+-- One organization default and one calendar per team
+CREATE UNIQUE INDEX IF NOT EXISTS idx_business_calendars_org_default
+    ON business_calendars(organization_id)
+    WHERE team_id IS NULL AND is_default = true;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_business_calendars_team
+    ON business_calendars(organization_id, team_id)
+    WHERE team_id IS NOT NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'business_calendars', 'read'),
+    ('p', 'role:admin', 'business_calendars', 'manage'),
+    ('p', 'role:accountant', 'business_calendars', 'read'),
+    ('p', 'role:sales', 'business_calendars', 'read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,46 @@
+-- Migration: Escalation Policies
+-- Description: Per-organization escalation matrices resolved against the HR manager hierarchy
+-- Version: 20250201000007
+
+-- ============================================================================
+-- Escalation Policies
+-- ============================================================================
+-- levels is an ordered array of {"targets": [...]} fallback chains. Each target
+-- has a kind (manager, skip_level_manager, department_manager, team_leader,
+-- org_role, user) plus a role or user_id where the kind needs one.
+-- Organizations without a 'default' policy use the built-in one.
+
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    code varchar(50) NOT NULL,
+    name varchar(255) NOT NULL,
+    levels jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT escalation_policies_org_code_unique UNIQUE (organization_id, code),
+    CONSTRAINT escalation_policies_levels_check CHECK (jsonb_typeof(levels) = 'array')
+);
+
+-- ============================================================================
+-- Hierarchy Lookups
+-- ============================================================================
+
+CREATE INDEX IF NOT EXISTS idx_employees_org_user
+    ON employees(organization_id, user_id)
+    WHERE user_id IS NOT NULL AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sales_teams_member_ids
+    ON sales_teams USING gin (member_ids);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'escalation_policies', 'read'),
+    ('p', 'role:admin', 'escalation_policies', 'manage'),
+    ('p', 'role:sales', 'escalation_policies', 'read'),
+    ('p', 'role:accountant', 'escalation_policies', 'read')
+ON CONFLICT DO NOTHING;
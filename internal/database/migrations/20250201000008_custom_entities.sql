@@ -0,0 +1,80 @@
+-- Migration: Custom Entities
+-- Description: Metadata-driven customer-defined objects with generic record storage
+-- Version: 20250201000008
+
+-- ============================================================================
+-- Entity Definitions
+-- ============================================================================
+-- fields is an array of {"name", "label", "type", "required", "options", "target"}.
+-- Relation fields point at a core record type (contact, lead, product, ...) or
+-- at another custom entity as "custom:<name>".
+
+CREATE TABLE IF NOT EXISTS custom_entity_definitions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(50) NOT NULL,
+    label varchar(255) NOT NULL,
+    plural_label varchar(255) NOT NULL,
+    description text,
+    title_field varchar(50) NOT NULL,
+    searchable boolean NOT NULL DEFAULT true,
+    fields jsonb NOT NULL DEFAULT '[]'::jsonb,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT custom_entity_definitions_org_name_unique UNIQUE (organization_id, name),
+    CONSTRAINT custom_entity_definitions_fields_check CHECK (jsonb_typeof(fields) = 'array')
+);
+
+-- ============================================================================
+-- Entity Records
+-- ============================================================================
+-- title mirrors the definition's title field so records can be searched and
+-- listed without unpacking data.
+
+CREATE TABLE IF NOT EXISTS custom_entity_records (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity_id uuid NOT NULL REFERENCES custom_entity_definitions(id) ON DELETE CASCADE,
+    title text NOT NULL DEFAULT '',
+    data jsonb NOT NULL DEFAULT '{}'::jsonb,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_entity_records_entity
+    ON custom_entity_records(organization_id, entity_id, created_at DESC)
+    WHERE deleted_at IS NULL;
+
+-- Equality filters are compiled to data @> '{...}' containment
+CREATE INDEX IF NOT EXISTS idx_custom_entity_records_data
+    ON custom_entity_records USING gin(data jsonb_path_ops)
+    WHERE deleted_at IS NULL;
+
+-- Quick search
+CREATE INDEX IF NOT EXISTS idx_custom_entity_records_title_trgm
+    ON custom_entity_records USING gin(title extensions.gin_trgm_ops)
+    WHERE deleted_at IS NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'custom_entities', 'read'),
+    ('p', 'role:admin', 'custom_entities', 'manage'),
+    ('p', 'role:admin', 'custom_entity_records', 'read'),
+    ('p', 'role:admin', 'custom_entity_records', 'create'),
+    ('p', 'role:admin', 'custom_entity_records', 'update'),
+    ('p', 'role:admin', 'custom_entity_records', 'delete'),
+    ('p', 'role:sales', 'custom_entities', 'read'),
+    ('p', 'role:sales', 'custom_entity_records', 'read'),
+    ('p', 'role:sales', 'custom_entity_records', 'create'),
+    ('p', 'role:sales', 'custom_entity_records', 'update'),
+    ('p', 'role:accountant', 'custom_entities', 'read'),
+    ('p', 'role:accountant', 'custom_entity_records', 'read'),
+    ('p', 'role:viewer', 'custom_entities', 'read'),
+    ('p', 'role:viewer', 'custom_entity_records', 'read')
+ON CONFLICT DO NOTHING;
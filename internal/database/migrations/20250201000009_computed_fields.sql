@@ -0,0 +1,42 @@
+-- Migration: Computed Fields
+-- Description: Formula and rollup field definitions evaluated server-side on read
+-- Version: 20250201000009
+
+-- ============================================================================
+-- Computed Field Definitions
+-- ============================================================================
+-- formula is arithmetic over the entity's numeric fields, e.g.
+-- "expected_revenue * probability / 100". rollup is
+-- {"relation", "aggregate", "field", "where"} and aggregates child records.
+-- Definitions are compiled to SQL when read, so values are never stored.
+
+CREATE TABLE IF NOT EXISTS computed_field_definitions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity varchar(50) NOT NULL,
+    name varchar(50) NOT NULL,
+    label varchar(255) NOT NULL,
+    kind varchar(20) NOT NULL,
+    formula text,
+    rollup jsonb,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT computed_field_definitions_org_entity_name_unique UNIQUE (organization_id, entity, name),
+    CONSTRAINT computed_field_definitions_kind_check CHECK (
+        (kind = 'formula' AND formula IS NOT NULL AND rollup IS NULL) OR
+        (kind = 'rollup' AND rollup IS NOT NULL AND formula IS NULL)
+    )
+);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'computed_fields', 'read'),
+    ('p', 'role:admin', 'computed_fields', 'manage'),
+    ('p', 'role:sales', 'computed_fields', 'read'),
+    ('p', 'role:accountant', 'computed_fields', 'read'),
+    ('p', 'role:viewer', 'computed_fields', 'read')
+ON CONFLICT DO NOTHING;
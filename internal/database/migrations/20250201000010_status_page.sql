@@ -0,0 +1,47 @@
+-- Migration: Public Status Page
+-- Description: Incident notes for the unauthenticated operational status page
+-- Version: 20250201000010
+
+-- ============================================================================
+-- Incidents
+-- ============================================================================
+-- Status page settings live under the "status_page" key of
+-- organizations.settings; the page is served at /public/v1/status/<slug>.
+-- Delivery volume and delays are aggregated from the delivery tables on read.
+
+CREATE TABLE IF NOT EXISTS status_page_incidents (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    title varchar(255) NOT NULL,
+    message text NOT NULL DEFAULT '',
+    severity varchar(20) NOT NULL DEFAULT 'minor',
+    status varchar(20) NOT NULL DEFAULT 'investigating',
+    region varchar(100),
+    started_at timestamptz NOT NULL DEFAULT now(),
+    resolved_at timestamptz,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT status_page_incidents_severity_check CHECK (severity IN ('info', 'minor', 'major', 'critical')),
+    CONSTRAINT status_page_incidents_status_check CHECK (status IN ('investigating', 'monitoring', 'resolved')),
+    CONSTRAINT status_page_incidents_resolved_check CHECK ((status = 'resolved') = (resolved_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_page_incidents_org_started
+    ON status_page_incidents(organization_id, started_at DESC);
+
+-- Region delays scan recent stops by planned arrival
+CREATE INDEX IF NOT EXISTS idx_delivery_route_stops_org_planned_arrival
+    ON delivery_route_stops(organization_id, planned_arrival_at);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'status_page', 'read'),
+    ('p', 'role:admin', 'status_page', 'manage'),
+    ('p', 'role:sales', 'status_page', 'read'),
+    ('p', 'role:viewer', 'status_page', 'read')
+ON CONFLICT DO NOTHING;
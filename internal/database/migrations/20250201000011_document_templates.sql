@@ -0,0 +1,128 @@
+-- Migration: Printable Document Templates
+-- Description: Branded, versioned HTML templates rendered to PDF, generated documents and async batches
+-- Version: 20250201000011
+
+-- ============================================================================
+-- Branding
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS document_branding (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    company_name text NOT NULL DEFAULT '',
+    logo_url text NOT NULL DEFAULT '',
+    primary_color text NOT NULL DEFAULT '',
+    accent_color text NOT NULL DEFAULT '',
+    address text NOT NULL DEFAULT '',
+    footer_text text NOT NULL DEFAULT '',
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- ============================================================================
+-- Templates
+-- ============================================================================
+-- Versions are immutable; generated documents record the version that
+-- produced them. active_version is the one used for generation.
+
+CREATE TABLE IF NOT EXISTS document_templates (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    code varchar(50) NOT NULL,
+    name varchar(255) NOT NULL,
+    kind varchar(30) NOT NULL,
+    active_version integer NOT NULL DEFAULT 1,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT document_templates_org_code_key UNIQUE (organization_id, code),
+    CONSTRAINT document_templates_kind_check CHECK (kind IN ('quote', 'invoice', 'proof_of_delivery', 'qc_certificate', 'route_manifest', 'other'))
+);
+
+CREATE TABLE IF NOT EXISTS document_template_versions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    template_id uuid NOT NULL REFERENCES document_templates(id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    body text NOT NULL,
+    page_size varchar(10) NOT NULL DEFAULT 'A4',
+    orientation varchar(10) NOT NULL DEFAULT 'Portrait',
+    note text NOT NULL DEFAULT '',
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT document_template_versions_template_version_key UNIQUE (template_id, version),
+    CONSTRAINT document_template_versions_page_size_check CHECK (page_size IN ('A4', 'Letter', 'Legal')),
+    CONSTRAINT document_template_versions_orientation_check CHECK (orientation IN ('Portrait', 'Landscape'))
+);
+
+-- ============================================================================
+-- Batches
+-- ============================================================================
+-- Items are processed in order; succeeded + failed is the resume position
+-- when a stale batch is claimed by another instance.
+
+CREATE TABLE IF NOT EXISTS document_batches (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    template_id uuid NOT NULL REFERENCES document_templates(id) ON DELETE CASCADE,
+    template_version integer NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    items jsonb NOT NULL,
+    total integer NOT NULL,
+    succeeded integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    errors jsonb NOT NULL DEFAULT '[]',
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    completed_at timestamptz,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT document_batches_status_check CHECK (status IN ('pending', 'processing', 'completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_batches_queue
+    ON document_batches(created_at)
+    WHERE status IN ('pending', 'processing');
+
+-- ============================================================================
+-- Generated documents
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS generated_documents (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    template_id uuid NOT NULL REFERENCES document_templates(id) ON DELETE CASCADE,
+    template_version integer NOT NULL,
+    kind varchar(30) NOT NULL,
+    record_id uuid,
+    batch_id uuid REFERENCES document_batches(id) ON DELETE SET NULL,
+    filename varchar(255) NOT NULL,
+    size integer NOT NULL,
+    sha256 char(64) NOT NULL,
+    content bytea NOT NULL,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_generated_documents_org_record
+    ON generated_documents(organization_id, record_id)
+    WHERE record_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_generated_documents_batch
+    ON generated_documents(batch_id)
+    WHERE batch_id IS NOT NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'documents', 'read'),
+    ('p', 'role:admin', 'documents', 'generate'),
+    ('p', 'role:admin', 'document_templates', 'manage'),
+    ('p', 'role:accountant', 'documents', 'read'),
+    ('p', 'role:accountant', 'documents', 'generate'),
+    ('p', 'role:sales', 'documents', 'read'),
+    ('p', 'role:sales', 'documents', 'generate'),
+    ('p', 'role:viewer', 'documents', 'read')
+ON CONFLICT DO NOTHING;
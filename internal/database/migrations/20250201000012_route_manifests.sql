@@ -0,0 +1,14 @@
+-- Migration: Route Manifests
+-- Description: Dangerous goods attributes on products for route manifests and loading lists
+-- Version: 20250201000012
+
+-- ============================================================================
+-- Dangerous goods
+-- ============================================================================
+-- Products with a hazard class or UN number are flagged on route manifests,
+-- together with their handling notes.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS hazmat_class varchar(10);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS un_number varchar(10);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS hazmat_notes text;
+
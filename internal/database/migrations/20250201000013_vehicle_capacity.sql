@@ -0,0 +1,44 @@
+-- Migration: Vehicle Capacity
+-- Description: Weight/volume limits and temperature compartments for delivery vehicles
+-- Version: 20250201000013
+
+-- ============================================================================
+-- Vehicle limits
+-- ============================================================================
+-- Overall payload limits in the product weight and volume units. NULL means
+-- the vehicle is not limited on that measure.
+
+ALTER TABLE delivery_vehicles ADD COLUMN IF NOT EXISTS max_weight numeric(12,3);
+ALTER TABLE delivery_vehicles ADD COLUMN IF NOT EXISTS max_volume numeric(12,3);
+
+-- ============================================================================
+-- Product temperature class
+-- ============================================================================
+-- Chilled and frozen goods must ride in a compartment of their class.
+-- NULL is treated as ambient.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS temperature_class varchar(10);
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_temperature_class_check;
+ALTER TABLE products ADD CONSTRAINT products_temperature_class_check
+    CHECK (temperature_class IS NULL OR temperature_class IN ('ambient', 'chilled', 'frozen'));
+
+-- ============================================================================
+-- Vehicle compartments
+-- ============================================================================
+-- A vehicle without compartments is a single ambient load space.
+
+CREATE TABLE IF NOT EXISTS delivery_vehicle_compartments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    vehicle_id uuid NOT NULL REFERENCES delivery_vehicles(id) ON DELETE CASCADE,
+    name varchar(100) NOT NULL,
+    temperature_class varchar(10) NOT NULL DEFAULT 'ambient',
+    max_weight numeric(12,3),
+    max_volume numeric(12,3),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT delivery_vehicle_compartments_class_check CHECK (temperature_class IN ('ambient', 'chilled', 'frozen')),
+    CONSTRAINT delivery_vehicle_compartments_name_unique UNIQUE (vehicle_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_vehicle_compartments_org ON delivery_vehicle_compartments(organization_id);
@@ -0,0 +1,37 @@
+-- Migration: Delivery Messages
+-- Description: Messages between dispatchers and drivers per route, with read receipts
+-- Version: 20250201000014
+
+-- ============================================================================
+-- Route messages
+-- ============================================================================
+-- A message belongs to a route and optionally to one of its stops. Photos
+-- are attachments uploaded before the message is sent.
+
+CREATE TABLE IF NOT EXISTS delivery_messages (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    route_id uuid NOT NULL REFERENCES delivery_routes(id) ON DELETE CASCADE,
+    stop_id uuid REFERENCES delivery_route_stops(id) ON DELETE SET NULL,
+    sender_id uuid NOT NULL,
+    sender_role varchar(20) NOT NULL,
+    body text NOT NULL DEFAULT '',
+    attachment_id uuid REFERENCES attachments(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT delivery_messages_sender_role_check CHECK (sender_role IN ('driver', 'dispatcher'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_messages_route ON delivery_messages(organization_id, route_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_delivery_messages_stop ON delivery_messages(stop_id) WHERE stop_id IS NOT NULL;
+
+-- ============================================================================
+-- Read receipts
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS delivery_message_reads (
+    message_id uuid NOT NULL REFERENCES delivery_messages(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    read_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (message_id, user_id)
+);
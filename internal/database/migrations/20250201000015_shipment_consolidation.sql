@@ -0,0 +1,62 @@
+-- Migration: Shipment Consolidation
+-- Description: Merging pending shipments to the same address or zone, with projected savings
+-- Version: 20250201000015
+
+-- ============================================================================
+-- Consolidated shipments
+-- ============================================================================
+-- A shipment merged into another keeps its picking but is delivered with the
+-- primary shipment, at the primary's stop.
+
+ALTER TABLE delivery_shipments
+    ADD COLUMN IF NOT EXISTS consolidated_into_id uuid REFERENCES delivery_shipments(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_delivery_shipments_consolidated_into
+    ON delivery_shipments(consolidated_into_id) WHERE consolidated_into_id IS NOT NULL;
+
+-- Merged shipments follow the status of their primary shipment
+CREATE OR REPLACE FUNCTION propagate_consolidated_shipment_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE delivery_shipments
+    SET status = NEW.status,
+        departed_at = NEW.departed_at,
+        arrived_at = NEW.arrived_at,
+        last_event_at = NEW.last_event_at,
+        updated_at = now()
+    WHERE consolidated_into_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS propagate_consolidated_shipment_status ON delivery_shipments;
+CREATE TRIGGER propagate_consolidated_shipment_status
+    AFTER UPDATE OF status, departed_at, arrived_at ON delivery_shipments
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status
+        OR OLD.departed_at IS DISTINCT FROM NEW.departed_at
+        OR OLD.arrived_at IS DISTINCT FROM NEW.arrived_at)
+    EXECUTE FUNCTION propagate_consolidated_shipment_status();
+
+-- ============================================================================
+-- Applied consolidations
+-- ============================================================================
+-- shipment_ids holds every consolidated shipment, the primary included.
+-- savings is the projection shown when the consolidation was applied.
+
+CREATE TABLE IF NOT EXISTS delivery_shipment_consolidations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    match_type varchar(20) NOT NULL,
+    primary_shipment_id uuid NOT NULL REFERENCES delivery_shipments(id) ON DELETE CASCADE,
+    shipment_ids uuid[] NOT NULL,
+    route_id uuid REFERENCES delivery_routes(id) ON DELETE SET NULL,
+    savings jsonb NOT NULL DEFAULT '{}'::jsonb,
+    automatic boolean NOT NULL DEFAULT false,
+    applied_by uuid,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT delivery_shipment_consolidations_match_check CHECK (match_type IN ('address', 'zone'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_shipment_consolidations_org
+    ON delivery_shipment_consolidations(organization_id, applied_at DESC);
@@ -0,0 +1,71 @@
+-- Migration: Stock Adjustments
+-- Description: Manual stock adjustments with reason codes, value-based approvals and shrinkage reporting
+-- Version: 20250201000016
+
+-- ============================================================================
+-- Adjustment reasons
+-- ============================================================================
+-- Reasons without an organization are system reasons available to every
+-- organization. An organization overrides a system reason, e.g. to deactivate
+-- it, with a reason of its own under the same code.
+
+CREATE TABLE IF NOT EXISTS inventory_adjustment_reasons (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid REFERENCES organizations(id) ON DELETE CASCADE,
+    code varchar(50) NOT NULL,
+    name varchar(255) NOT NULL,
+    description text,
+    requires_note boolean NOT NULL DEFAULT false,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_adjustment_reasons_system_code
+    ON inventory_adjustment_reasons(code) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_adjustment_reasons_org_code
+    ON inventory_adjustment_reasons(organization_id, code) WHERE organization_id IS NOT NULL;
+
+INSERT INTO inventory_adjustment_reasons (code, name, description, requires_note) VALUES
+    ('damage', 'Damage', 'Stock damaged in storage or handling', false),
+    ('theft', 'Theft', 'Stock lost to theft', true),
+    ('expiry', 'Expiry', 'Stock past its expiry date', false),
+    ('correction', 'Correction', 'Correction of a counting or recording error', true),
+    ('found', 'Found', 'Stock found that was not on record', false)
+ON CONFLICT (code) WHERE organization_id IS NULL DO NOTHING;
+
+-- ============================================================================
+-- Stock adjustments
+-- ============================================================================
+-- quantity is signed, negative for losses. value is quantity at the product's
+-- standard price when the adjustment was requested. Applying an adjustment
+-- books a done stock move from or to the organization's inventory loss
+-- location.
+
+CREATE TABLE IF NOT EXISTS inventory_stock_adjustments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    product_id uuid NOT NULL REFERENCES products(id),
+    location_id uuid NOT NULL REFERENCES stock_locations(id),
+    reason_code varchar(50) NOT NULL,
+    note text,
+    quantity_before numeric(15,4) NOT NULL DEFAULT 0,
+    quantity numeric(15,4) NOT NULL,
+    unit_cost numeric(15,2) NOT NULL DEFAULT 0,
+    value numeric(15,2) NOT NULL DEFAULT 0,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    requested_by uuid NOT NULL,
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    reviewed_by uuid,
+    reviewed_at timestamptz,
+    review_note text,
+    applied_at timestamptz,
+    stock_move_id uuid REFERENCES stock_moves(id) ON DELETE SET NULL,
+    CONSTRAINT inventory_stock_adjustments_status_check CHECK (status IN ('pending', 'applied', 'rejected')),
+    CONSTRAINT inventory_stock_adjustments_quantity_check CHECK (quantity <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_stock_adjustments_org_status
+    ON inventory_stock_adjustments(organization_id, status, requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_stock_adjustments_org_applied
+    ON inventory_stock_adjustments(organization_id, applied_at) WHERE status = 'applied';
@@ -0,0 +1,161 @@
+-- Migration: Consignment Stock
+-- Description: Vendor-owned consignment stock, consumption billing and exclusion of consigned stock from valuation
+-- Version: 20250201000017
+
+-- ============================================================================
+-- Consignment agreements
+-- ============================================================================
+-- Consigned stock is stock quants owned by the vendor (stock_quants.owner_id).
+-- Consuming it transfers the quantity to a quant without owner and records
+-- what the organization owes the vendor at the agreement's price.
+
+CREATE TABLE IF NOT EXISTS inventory_consignment_agreements (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    vendor_id uuid NOT NULL REFERENCES contacts(id),
+    reference varchar(255),
+    billing_policy varchar(20) NOT NULL DEFAULT 'consumption',
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT inventory_consignment_agreements_vendor_unique UNIQUE (organization_id, vendor_id),
+    CONSTRAINT inventory_consignment_agreements_billing_check CHECK (billing_policy IN ('consumption', 'manual'))
+);
+
+CREATE TABLE IF NOT EXISTS inventory_consignment_items (
+    agreement_id uuid NOT NULL REFERENCES inventory_consignment_agreements(id) ON DELETE CASCADE,
+    product_id uuid NOT NULL REFERENCES products(id),
+    unit_price numeric(15,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (agreement_id, product_id)
+);
+
+-- ============================================================================
+-- Consumptions
+-- ============================================================================
+-- invoice_id is the draft vendor bill the consumption was billed on.
+
+CREATE TABLE IF NOT EXISTS inventory_consignment_consumptions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    agreement_id uuid NOT NULL REFERENCES inventory_consignment_agreements(id),
+    vendor_id uuid NOT NULL REFERENCES contacts(id),
+    product_id uuid NOT NULL REFERENCES products(id),
+    location_id uuid NOT NULL REFERENCES stock_locations(id),
+    lot_id uuid REFERENCES stock_lots(id),
+    quantity numeric(15,4) NOT NULL,
+    unit_price numeric(15,2) NOT NULL DEFAULT 0,
+    amount numeric(15,2) NOT NULL DEFAULT 0,
+    reference varchar(255),
+    consumed_by uuid NOT NULL,
+    consumed_at timestamptz NOT NULL DEFAULT now(),
+    invoice_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    billed_at timestamptz,
+    CONSTRAINT inventory_consignment_consumptions_quantity_check CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_consignment_consumptions_agreement
+    ON inventory_consignment_consumptions(agreement_id, consumed_at);
+CREATE INDEX IF NOT EXISTS idx_inventory_consignment_consumptions_unbilled
+    ON inventory_consignment_consumptions(agreement_id) WHERE invoice_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_stock_quants_owner
+    ON stock_quants(organization_id, owner_id) WHERE owner_id IS NOT NULL;
+
+-- ============================================================================
+-- Valuation
+-- ============================================================================
+-- Stock owned by someone else, consigned stock included, is not the
+-- organization's asset and is left out of valuation.
+
+CREATE OR REPLACE VIEW inventory_valuation AS
+SELECT
+    p.organization_id,
+    p.id as product_id,
+    p.name as product_name,
+    p.default_code,
+    p.category_id,
+    p.valuation_method,
+    SUM(sq.quantity) as total_quantity,
+    SUM(sq.quantity * p.standard_price) as current_value,
+    SUM(sq.quantity * p.list_price) as retail_value,
+    SUM(sq.quantity * p.standard_price) - SUM(sq.quantity * p.standard_price) as unrealized_gain_loss,
+    p.currency_id,
+    p.uom_id,
+    p.active,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN stock_quants sq ON p.id = sq.product_id AND p.organization_id = sq.organization_id
+WHERE sq.quantity > 0 AND sq.owner_id IS NULL
+GROUP BY p.organization_id, p.id;
+
+CREATE OR REPLACE FUNCTION analytics_stock_valuation(
+    p_organization_id uuid,
+    p_location_id uuid DEFAULT NULL
+)
+RETURNS TABLE (
+    location_id uuid,
+    location_name varchar,
+    product_id uuid,
+    product_name varchar,
+    quantity_on_hand numeric,
+    unit_cost numeric,
+    total_value numeric
+)
+LANGUAGE plpgsql
+STABLE
+AS $$
+BEGIN
+    RETURN QUERY
+    SELECT
+        sl.id,
+        sl.name,
+        p.id,
+        p.name,
+        sq.quantity,
+        p.standard_price,
+        sq.quantity * p.standard_price
+    FROM stock_quants sq
+    JOIN stock_locations sl ON sq.location_id = sl.id
+    JOIN products p ON sq.product_id = p.id
+    WHERE sq.organization_id = p_organization_id
+      AND sl.usage = 'internal'
+      AND sq.quantity > 0
+      AND sq.owner_id IS NULL
+      AND (p_location_id IS NULL OR sl.id = p_location_id)
+    ORDER BY sl.name, total_value DESC;
+END;
+$$;
+
+CREATE OR REPLACE VIEW view_inventory_valuation AS
+SELECT
+    p.id as product_id,
+    p.name as product_name,
+    p.default_code as product_code,
+    pc.name as category_name,
+    SUM(sq.quantity) as total_qty,
+    p.standard_price as unit_cost,
+    p.list_price as sale_price,
+    SUM(sq.quantity * p.standard_price) as total_cost_value,
+    SUM(sq.quantity * p.list_price) as total_sale_value,
+    SUM(sq.quantity * (p.list_price - p.standard_price)) as potential_margin,
+    -- Location breakdown
+    jsonb_object_agg(
+        sl.name,
+        jsonb_build_object(
+            'quantity', sq.quantity,
+            'value', sq.quantity * p.standard_price
+        )
+    ) as location_breakdown
+FROM stock_quants sq
+JOIN products p ON sq.product_id = p.id
+LEFT JOIN product_categories pc ON p.category_id = pc.id
+JOIN stock_locations sl ON sq.location_id = sl.id
+WHERE sl.usage = 'internal'
+  AND p.product_type = 'storable'
+  AND sq.owner_id IS NULL
+  AND sq.organization_id = get_current_user_organization_id()
+GROUP BY p.id, p.name, p.default_code, pc.name, p.standard_price, p.list_price
+HAVING SUM(sq.quantity) > 0
+ORDER BY SUM(sq.quantity * p.standard_price) DESC;
+
+ALTER VIEW view_inventory_valuation SET (security_invoker = on);
@@ -0,0 +1,81 @@
+-- Migration: Dropship and Intercompany Orders
+-- Description: Dropship sales order lines purchased from vendors who ship to the customer, vendor tracking and intercompany invoicing
+-- Version: 20250201000018
+
+-- ============================================================================
+-- Dropship lines
+-- ============================================================================
+-- A dropship line is not delivered from stock: it is purchased from
+-- dropship_vendor_id, who ships it straight to the order's shipping partner.
+-- purchase_line_id is set once the line has been ordered from the vendor.
+
+ALTER TABLE sales_order_lines
+    ADD COLUMN IF NOT EXISTS route varchar(20) NOT NULL DEFAULT 'stock',
+    ADD COLUMN IF NOT EXISTS dropship_vendor_id uuid REFERENCES contacts(id),
+    ADD COLUMN IF NOT EXISTS dropship_cost numeric(15,2),
+    ADD COLUMN IF NOT EXISTS purchase_line_id uuid REFERENCES purchase_order_lines(id) ON DELETE SET NULL;
+
+ALTER TABLE sales_order_lines DROP CONSTRAINT IF EXISTS sales_order_lines_route_check;
+ALTER TABLE sales_order_lines
+    ADD CONSTRAINT sales_order_lines_route_check CHECK (route IN ('stock', 'dropship'));
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_lines_dropship_pending
+    ON sales_order_lines(order_id) WHERE route = 'dropship' AND purchase_line_id IS NULL;
+
+-- ============================================================================
+-- Dropship orders
+-- ============================================================================
+-- One purchase order per vendor and sales order. The vendor's tracking data
+-- is kept on a delivery shipment, whose picking moves the goods from the
+-- supplier location straight to the customer location.
+
+CREATE TABLE IF NOT EXISTS sales_dropships (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    sales_order_id uuid NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    purchase_order_id uuid NOT NULL UNIQUE REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    vendor_id uuid NOT NULL REFERENCES contacts(id),
+    customer_id uuid NOT NULL REFERENCES contacts(id),
+    picking_id uuid REFERENCES stock_pickings(id) ON DELETE SET NULL,
+    shipment_id uuid REFERENCES delivery_shipments(id) ON DELETE SET NULL,
+    tracking_url text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_dropships_order
+    ON sales_dropships(organization_id, sales_order_id);
+
+-- ============================================================================
+-- Intercompany
+-- ============================================================================
+-- A company trades with the other companies of its organization through the
+-- contact that represents it. An order whose customer is such a contact is an
+-- intercompany sale, invoiced to the buying company with a mirrored vendor bill.
+
+CREATE TABLE IF NOT EXISTS intercompany_partners (
+    company_id uuid PRIMARY KEY REFERENCES companies(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    partner_id uuid NOT NULL REFERENCES contacts(id),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT intercompany_partners_partner_unique UNIQUE (organization_id, partner_id)
+);
+
+CREATE TABLE IF NOT EXISTS intercompany_invoices (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    sales_order_id uuid NOT NULL UNIQUE REFERENCES sales_orders(id) ON DELETE CASCADE,
+    seller_company_id uuid NOT NULL REFERENCES companies(id),
+    buyer_company_id uuid NOT NULL REFERENCES companies(id),
+    customer_invoice_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    vendor_bill_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    amount_total numeric(15,2) NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    CONSTRAINT intercompany_invoices_companies_check CHECK (seller_company_id <> buyer_company_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_intercompany_invoices_org
+    ON intercompany_invoices(organization_id, created_at);
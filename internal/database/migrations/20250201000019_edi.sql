@@ -0,0 +1,73 @@
+-- Migration: EDI Integration
+-- Description: X12/EDIFACT trading partners and the log of inbound purchase orders and outbound ship notices and invoices
+-- Version: 20250201000019
+
+-- ============================================================================
+-- Trading partners
+-- ============================================================================
+-- A trading partner is a customer contact exchanging EDI documents. Inbound
+-- interchanges are matched to a partner by their sender ID and qualifier;
+-- outbound interchanges are numbered from last_control_number.
+
+CREATE TABLE IF NOT EXISTS edi_partners (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    standard varchar(10) NOT NULL,
+    interchange_id varchar(35) NOT NULL,
+    interchange_qualifier varchar(4) NOT NULL DEFAULT '',
+    our_interchange_id varchar(35) NOT NULL,
+    our_interchange_qualifier varchar(4) NOT NULL DEFAULT '',
+    test boolean NOT NULL DEFAULT false,
+    active boolean NOT NULL DEFAULT true,
+    last_control_number bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT edi_partners_standard_check CHECK (standard IN ('x12', 'edifact')),
+    CONSTRAINT edi_partners_contact_unique UNIQUE (organization_id, contact_id),
+    CONSTRAINT edi_partners_interchange_unique UNIQUE (organization_id, interchange_qualifier, interchange_id)
+);
+
+-- ============================================================================
+-- Message log
+-- ============================================================================
+-- One row per document received or generated. Inbound rows keep the whole
+-- interchange they arrived in; interchanges that cannot be parsed are logged
+-- as failed without a document type. entity_type/entity_id point at the
+-- sales order, shipment or invoice the document maps to.
+
+CREATE TABLE IF NOT EXISTS edi_messages (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    partner_id uuid REFERENCES edi_partners(id) ON DELETE SET NULL,
+    direction varchar(10) NOT NULL,
+    standard varchar(10),
+    document_type varchar(30),
+    interchange_control_number varchar(20),
+    control_number varchar(20),
+    reference varchar(255),
+    status varchar(20) NOT NULL,
+    error text,
+    entity_type varchar(30),
+    entity_id uuid,
+    payload text NOT NULL,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    sent_at timestamptz,
+
+    CONSTRAINT edi_messages_direction_check CHECK (direction IN ('inbound', 'outbound')),
+    CONSTRAINT edi_messages_status_check CHECK (status IN ('processed', 'failed', 'generated', 'sent')),
+    CONSTRAINT edi_messages_document_type_check CHECK (document_type IN ('purchase_order', 'ship_notice', 'invoice'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_edi_messages_org_created ON edi_messages(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_edi_messages_entity ON edi_messages(entity_type, entity_id) WHERE entity_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_edi_messages_partner ON edi_messages(partner_id, created_at DESC);
+
+-- Sales orders created from an inbound purchase order are looked up by the
+-- customer's PO number to reject duplicates and to reference it in ASNs and invoices
+CREATE INDEX IF NOT EXISTS idx_sales_orders_client_order_ref
+    ON sales_orders(organization_id, client_order_ref) WHERE client_order_ref IS NOT NULL;
+
@@ -0,0 +1,78 @@
+-- Migration: Sales Promotions
+-- Description: Promotion and discount rules (percentage, fixed, buy X get Y) with coupon codes, customer segment and date constraints, applied to quotes and orders as reward lines
+-- Version: 20250201000020
+
+-- ============================================================================
+-- Promotions
+-- ============================================================================
+-- product_ids restricts percentage and fixed promotions to some products;
+-- empty means the whole order. Buy X get Y promotions give reward_quantity
+-- units of reward_product_id (or buy_product_id) for every buy_quantity
+-- units of buy_product_id. Promotions with a coupon_code only apply when the
+-- code is entered. A promotion that is not stackable is never combined with
+-- others: the order gets either it or the stackable promotions, whichever
+-- discounts more.
+
+CREATE TABLE IF NOT EXISTS sales_promotions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    description text,
+    promotion_type varchar(20) NOT NULL,
+    value numeric(15,2) NOT NULL DEFAULT 0,
+    coupon_code varchar(50),
+    product_ids uuid[] NOT NULL DEFAULT '{}',
+    min_order_amount numeric(15,2) NOT NULL DEFAULT 0,
+    buy_product_id uuid REFERENCES products(id),
+    buy_quantity numeric(15,4),
+    reward_product_id uuid REFERENCES products(id),
+    reward_quantity numeric(15,4),
+    segment_id uuid REFERENCES contact_segments(id) ON DELETE SET NULL,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    stackable boolean NOT NULL DEFAULT true,
+    priority integer NOT NULL DEFAULT 10,
+    usage_limit integer,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT sales_promotions_type_check CHECK (promotion_type IN ('percentage', 'fixed', 'buy_x_get_y')),
+    CONSTRAINT sales_promotions_value_check CHECK (
+        (promotion_type = 'percentage' AND value > 0 AND value <= 100)
+        OR (promotion_type = 'fixed' AND value > 0)
+        OR (promotion_type = 'buy_x_get_y' AND buy_product_id IS NOT NULL AND buy_quantity > 0 AND reward_quantity > 0)
+    ),
+    CONSTRAINT sales_promotions_dates_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at),
+    CONSTRAINT sales_promotions_usage_limit_check CHECK (usage_limit IS NULL OR usage_limit > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_promotions_coupon
+    ON sales_promotions(organization_id, upper(coupon_code)) WHERE coupon_code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_sales_promotions_active ON sales_promotions(organization_id) WHERE active;
+
+-- ============================================================================
+-- Applied promotions
+-- ============================================================================
+-- Each applied promotion adds a reward line with a negative price to the
+-- order. Repricing an order replaces its reward lines and applications.
+
+ALTER TABLE sales_order_lines
+    ADD COLUMN IF NOT EXISTS promotion_id uuid REFERENCES sales_promotions(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS sales_order_promotions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    order_id uuid NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    promotion_id uuid NOT NULL REFERENCES sales_promotions(id) ON DELETE CASCADE,
+    coupon_code varchar(50),
+    discount_amount numeric(15,2) NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    applied_by uuid,
+
+    CONSTRAINT sales_order_promotions_unique UNIQUE (order_id, promotion_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_promotions_promotion ON sales_order_promotions(promotion_id);
@@ -0,0 +1,133 @@
+-- Migration: Sales Commissions
+-- Description: Commission plans (flat, tiered, per-product), split credit on team deals, accruals on won leads and paid invoices, disputes and adjustments, and payouts for payroll export
+-- Version: 20250201000021
+
+-- ============================================================================
+-- Plans
+-- ============================================================================
+-- A plan pays on won leads (expected revenue) or on paid customer invoices
+-- (untaxed amount). Flat plans pay rate percent. Tiered plans pay each
+-- tier's rate on the part of the rep's monthly base within the tier; tiers
+-- is a JSON array of {"threshold": ..., "rate": ...} starting at 0.
+-- Per-product plans pay the product's rate, or rate for other products.
+-- Deals closed before effective_from do not accrue.
+
+CREATE TABLE IF NOT EXISTS commission_plans (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    description text,
+    basis varchar(20) NOT NULL,
+    plan_type varchar(20) NOT NULL,
+    rate numeric(7,4) NOT NULL DEFAULT 0,
+    tiers jsonb NOT NULL DEFAULT '[]'::jsonb,
+    effective_from date NOT NULL DEFAULT CURRENT_DATE,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT commission_plans_name_unique UNIQUE (organization_id, name),
+    CONSTRAINT commission_plans_basis_check CHECK (basis IN ('lead_won', 'invoice_paid')),
+    CONSTRAINT commission_plans_type_check CHECK (plan_type IN ('flat', 'tiered', 'per_product')),
+    CONSTRAINT commission_plans_rate_check CHECK (rate >= 0 AND rate <= 100)
+);
+
+CREATE TABLE IF NOT EXISTS commission_plan_rates (
+    plan_id uuid NOT NULL REFERENCES commission_plans(id) ON DELETE CASCADE,
+    product_id uuid NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    rate numeric(7,4) NOT NULL,
+
+    PRIMARY KEY (plan_id, product_id),
+    CONSTRAINT commission_plan_rates_rate_check CHECK (rate >= 0 AND rate <= 100)
+);
+
+-- A rep is on at most one plan per basis; the service enforces it
+CREATE TABLE IF NOT EXISTS commission_plan_members (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    plan_id uuid NOT NULL REFERENCES commission_plans(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (plan_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_plan_members_user ON commission_plan_members(organization_id, user_id);
+
+-- ============================================================================
+-- Splits
+-- ============================================================================
+-- Team deals split the credit between reps; shares add up to 100. Deals
+-- without splits credit the lead's assignee or the invoice's salesperson.
+
+CREATE TABLE IF NOT EXISTS commission_splits (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    source_type varchar(20) NOT NULL,
+    source_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    share numeric(5,2) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT commission_splits_unique UNIQUE (organization_id, source_type, source_id, user_id),
+    CONSTRAINT commission_splits_source_check CHECK (source_type IN ('lead', 'invoice')),
+    CONSTRAINT commission_splits_share_check CHECK (share > 0 AND share <= 100)
+);
+
+-- ============================================================================
+-- Payouts and entries
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS commission_payouts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    period_end date NOT NULL,
+    total numeric(15,2) NOT NULL DEFAULT 0,
+    entry_count integer NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_payouts_org ON commission_payouts(organization_id, period_end DESC);
+
+-- Accruals are written once per deal and rep; corrections are adjustment
+-- entries, so paid amounts never change. An entry with an open dispute
+-- (disputed_at set, resolved_at not) is held back from payouts.
+
+CREATE TABLE IF NOT EXISTS commission_entries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    plan_id uuid REFERENCES commission_plans(id) ON DELETE SET NULL,
+    entry_type varchar(20) NOT NULL,
+    source_type varchar(20),
+    source_id uuid,
+    source_name varchar(255),
+    earned_on date NOT NULL,
+    base_amount numeric(15,2) NOT NULL DEFAULT 0,
+    share numeric(5,2) NOT NULL DEFAULT 100,
+    rate numeric(7,4) NOT NULL DEFAULT 0,
+    amount numeric(15,2) NOT NULL,
+    adjusts_entry_id uuid REFERENCES commission_entries(id) ON DELETE SET NULL,
+    note text,
+    dispute_reason text,
+    disputed_by uuid,
+    disputed_at timestamptz,
+    resolution_note text,
+    resolved_by uuid,
+    resolved_at timestamptz,
+    payout_id uuid REFERENCES commission_payouts(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT commission_entries_type_check CHECK (entry_type IN ('accrual', 'adjustment')),
+    CONSTRAINT commission_entries_source_check CHECK (source_type IS NULL OR source_type IN ('lead', 'invoice')),
+    CONSTRAINT commission_entries_accrual_check CHECK (entry_type <> 'accrual' OR (source_type IS NOT NULL AND source_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_entries_accrual
+    ON commission_entries(organization_id, source_type, source_id, user_id) WHERE entry_type = 'accrual';
+CREATE INDEX IF NOT EXISTS idx_commission_entries_user ON commission_entries(organization_id, user_id, earned_on);
+CREATE INDEX IF NOT EXISTS idx_commission_entries_unpaid ON commission_entries(organization_id, earned_on) WHERE payout_id IS NULL;
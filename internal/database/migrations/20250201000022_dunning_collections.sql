@@ -0,0 +1,131 @@
+-- Migration: Dunning and Collections
+-- Description: Escalating dunning levels with email templates and fees, dunning notices sent on overdue customer invoices, promise-to-pay tracking, collection agent assignments and daily aging snapshots
+-- Version: 20250201000022
+
+-- ============================================================================
+-- Dunning levels
+-- ============================================================================
+-- An invoice reaches a level once it is days_overdue days past its due date.
+-- Levels escalate: a higher level needs more days overdue. The notice of a
+-- level charges fee_fixed plus fee_percent of the amount due, and its email
+-- is rendered from email_subject and email_body as Go text templates.
+
+CREATE TABLE IF NOT EXISTS dunning_levels (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    level integer NOT NULL,
+    name varchar(255) NOT NULL,
+    days_overdue integer NOT NULL,
+    fee_fixed numeric(15,2) NOT NULL DEFAULT 0,
+    fee_percent numeric(7,4) NOT NULL DEFAULT 0,
+    email_subject text NOT NULL,
+    email_body text NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT dunning_levels_level_unique UNIQUE (organization_id, level),
+    CONSTRAINT dunning_levels_level_check CHECK (level >= 1),
+    CONSTRAINT dunning_levels_days_check CHECK (days_overdue >= 1),
+    CONSTRAINT dunning_levels_fee_check CHECK (fee_fixed >= 0 AND fee_percent >= 0 AND fee_percent <= 100)
+);
+
+-- ============================================================================
+-- Notices
+-- ============================================================================
+-- One notice per invoice and level. Notices that failed to send are retried
+-- by the next run; skipped notices (no customer email or no mail server)
+-- still count as reached so the agent follows up by other means.
+
+CREATE TABLE IF NOT EXISTS dunning_notices (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    invoice_id uuid NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    level_id uuid REFERENCES dunning_levels(id) ON DELETE SET NULL,
+    level integer NOT NULL,
+    days_overdue integer NOT NULL,
+    amount_due numeric(15,2) NOT NULL,
+    fee numeric(15,2) NOT NULL DEFAULT 0,
+    recipient varchar(255),
+    subject text NOT NULL,
+    body text NOT NULL,
+    status varchar(20) NOT NULL,
+    error text,
+    sent_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT dunning_notices_level_unique UNIQUE (invoice_id, level),
+    CONSTRAINT dunning_notices_status_check CHECK (status IN ('sent', 'failed', 'skipped'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_dunning_notices_org ON dunning_notices(organization_id, sent_at DESC);
+
+-- ============================================================================
+-- Promises to pay
+-- ============================================================================
+-- A customer promises to pay promised_amount of an invoice by promised_date.
+-- Dunning pauses on the invoice while the promise is open. After the date
+-- the promise is kept when the amount due dropped by the promised amount
+-- from amount_due_at_promise, and broken otherwise.
+
+CREATE TABLE IF NOT EXISTS collection_promises (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    invoice_id uuid NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    promised_amount numeric(15,2) NOT NULL,
+    promised_date date NOT NULL,
+    amount_due_at_promise numeric(15,2) NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'open',
+    note text,
+    resolved_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT collection_promises_status_check CHECK (status IN ('open', 'kept', 'broken', 'cancelled')),
+    CONSTRAINT collection_promises_amount_check CHECK (promised_amount > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_promises_open
+    ON collection_promises(invoice_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_collection_promises_org ON collection_promises(organization_id, status, promised_date);
+
+-- ============================================================================
+-- Agent assignments
+-- ============================================================================
+-- Each customer is worked by one collection agent; the agent's work queue
+-- holds the overdue invoices of their customers.
+
+CREATE TABLE IF NOT EXISTS collection_assignments (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    partner_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    agent_id uuid NOT NULL,
+    assigned_at timestamptz NOT NULL DEFAULT now(),
+    assigned_by uuid,
+
+    PRIMARY KEY (organization_id, partner_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_agent ON collection_assignments(organization_id, agent_id);
+
+-- ============================================================================
+-- Aging snapshots
+-- ============================================================================
+-- The amount due on open customer invoices by days past due, recorded once
+-- a day so aging can be compared over time.
+
+CREATE TABLE IF NOT EXISTS collection_aging_snapshots (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    snapshot_date date NOT NULL,
+    not_due numeric(15,2) NOT NULL DEFAULT 0,
+    days_1_30 numeric(15,2) NOT NULL DEFAULT 0,
+    days_31_60 numeric(15,2) NOT NULL DEFAULT 0,
+    days_61_90 numeric(15,2) NOT NULL DEFAULT 0,
+    days_over_90 numeric(15,2) NOT NULL DEFAULT 0,
+    overdue_invoices integer NOT NULL DEFAULT 0,
+    average_days_overdue numeric(9,2) NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (organization_id, snapshot_date)
+);
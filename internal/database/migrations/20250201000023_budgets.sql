@@ -0,0 +1,80 @@
+-- Migration: Budgets
+-- Description: Spending budgets per account, department or project and period, with actuals from posted journal lines, commitments from confirmed purchase orders and threshold alerts
+-- Version: 20250201000023
+
+-- ============================================================================
+-- Analytic accounts of departments and projects
+-- ============================================================================
+-- Journal lines and purchase order lines reach a department or project
+-- through its analytic account. The account is created when a budget first
+-- targets the department or project.
+
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS analytic_account_id uuid REFERENCES analytic_accounts(id) ON DELETE SET NULL;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS analytic_account_id uuid REFERENCES analytic_accounts(id) ON DELETE SET NULL;
+
+-- ============================================================================
+-- Budgets
+-- ============================================================================
+-- A budget caps spending over a period on an account, a department or a
+-- project, or an account within a department or project. Actuals are the
+-- debit minus credit of posted journal lines dated in the period; without
+-- an account only expense and depreciation accounts count. Commitments are
+-- the uninvoiced part of confirmed purchase order lines ordered in the
+-- period; purchase lines carry no account, so budgets on an account count
+-- no commitments. thresholds are the consumption percentages that raise
+-- an alert.
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    account_id uuid REFERENCES account_accounts(id) ON DELETE CASCADE,
+    department_id uuid REFERENCES departments(id) ON DELETE CASCADE,
+    project_id uuid REFERENCES projects(id) ON DELETE CASCADE,
+    period_start date NOT NULL,
+    period_end date NOT NULL,
+    amount numeric(15,2) NOT NULL,
+    include_commitments boolean NOT NULL DEFAULT true,
+    thresholds integer[] NOT NULL DEFAULT '{80,100}',
+    active boolean NOT NULL DEFAULT true,
+    notes text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT budgets_name_unique UNIQUE (organization_id, name),
+    CONSTRAINT budgets_period_check CHECK (period_end >= period_start),
+    CONSTRAINT budgets_amount_check CHECK (amount > 0),
+    CONSTRAINT budgets_scope_check CHECK (
+        (account_id IS NOT NULL OR department_id IS NOT NULL OR project_id IS NOT NULL)
+        AND (department_id IS NULL OR project_id IS NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_period ON budgets(organization_id, period_start, period_end);
+
+-- ============================================================================
+-- Alerts
+-- ============================================================================
+-- Raised once per budget and threshold, when actuals plus commitments
+-- first reach the threshold percentage of the budget.
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    budget_id uuid NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    threshold integer NOT NULL,
+    budget_amount numeric(15,2) NOT NULL,
+    actual numeric(15,2) NOT NULL,
+    committed numeric(15,2) NOT NULL,
+    consumed_percent numeric(9,2) NOT NULL,
+    triggered_at timestamptz NOT NULL DEFAULT now(),
+    acknowledged_by uuid,
+    acknowledged_at timestamptz,
+
+    CONSTRAINT budget_alerts_threshold_unique UNIQUE (budget_id, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_open ON budget_alerts(organization_id, triggered_at DESC)
+    WHERE acknowledged_at IS NULL;
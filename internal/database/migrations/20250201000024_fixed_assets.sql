@@ -0,0 +1,145 @@
+-- Migration: Fixed Assets
+-- Description: Fixed asset register with categories, depreciation schedules posted as monthly journal entries, disposals and transfers
+-- Version: 20250201000024
+
+-- ============================================================================
+-- Journal entries without a partner
+-- ============================================================================
+-- Depreciation and disposal entries belong to no customer or vendor.
+-- Invoices and refunds still require one.
+
+ALTER TABLE invoices ALTER COLUMN partner_id DROP NOT NULL;
+ALTER TABLE invoices ADD CONSTRAINT invoices_partner_check CHECK (move_type = 'entry' OR partner_id IS NOT NULL);
+
+-- ============================================================================
+-- Categories
+-- ============================================================================
+-- A category sets how its assets depreciate by default and where their
+-- entries post: depreciation debits expense_account_id and credits
+-- depreciation_account_id (accumulated depreciation); disposals clear
+-- asset_account_id and book the result to gain_account_id or
+-- loss_account_id. declining_rate is the yearly rate of declining balance
+-- depreciation, in percent.
+
+CREATE TABLE IF NOT EXISTS asset_categories (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    method varchar(30) NOT NULL DEFAULT 'straight_line',
+    useful_life_months integer NOT NULL,
+    declining_rate numeric(7,4) NOT NULL DEFAULT 0,
+    journal_id uuid NOT NULL REFERENCES account_journals(id),
+    asset_account_id uuid NOT NULL REFERENCES account_accounts(id),
+    depreciation_account_id uuid NOT NULL REFERENCES account_accounts(id),
+    expense_account_id uuid NOT NULL REFERENCES account_accounts(id),
+    gain_account_id uuid REFERENCES account_accounts(id),
+    loss_account_id uuid REFERENCES account_accounts(id),
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT asset_categories_name_unique UNIQUE (organization_id, name),
+    CONSTRAINT asset_categories_method_check CHECK (method IN ('straight_line', 'declining_balance')),
+    CONSTRAINT asset_categories_life_check CHECK (useful_life_months > 0)
+);
+
+-- ============================================================================
+-- Assets
+-- ============================================================================
+-- Assets start as drafts. Confirming one computes its depreciation
+-- schedule and makes it running; it is fully depreciated once the last
+-- line is posted, and disposed when sold or scrapped. An asset acquired
+-- from a vendor bill keeps the bill line, which can back a single asset.
+-- accumulated_depreciation is the total of the posted schedule lines.
+
+CREATE TABLE IF NOT EXISTS fixed_assets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    category_id uuid NOT NULL REFERENCES asset_categories(id),
+    code varchar(64),
+    name varchar(255) NOT NULL,
+    vendor_id uuid REFERENCES contacts(id) ON DELETE SET NULL,
+    bill_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    bill_line_id uuid REFERENCES invoice_lines(id) ON DELETE SET NULL,
+    acquisition_date date NOT NULL,
+    depreciation_start date NOT NULL,
+    cost numeric(15,2) NOT NULL,
+    salvage_value numeric(15,2) NOT NULL DEFAULT 0,
+    method varchar(30) NOT NULL,
+    useful_life_months integer NOT NULL,
+    declining_rate numeric(7,4) NOT NULL DEFAULT 0,
+    department_id uuid REFERENCES departments(id) ON DELETE SET NULL,
+    location varchar(255),
+    custodian_id uuid,
+    state varchar(30) NOT NULL DEFAULT 'draft',
+    accumulated_depreciation numeric(15,2) NOT NULL DEFAULT 0,
+    disposal_date date,
+    disposal_proceeds numeric(15,2),
+    disposal_gain_loss numeric(15,2),
+    disposal_move_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    notes text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT fixed_assets_code_unique UNIQUE (organization_id, code),
+    CONSTRAINT fixed_assets_bill_line_unique UNIQUE (bill_line_id),
+    CONSTRAINT fixed_assets_method_check CHECK (method IN ('straight_line', 'declining_balance')),
+    CONSTRAINT fixed_assets_state_check CHECK (state IN ('draft', 'running', 'fully_depreciated', 'disposed')),
+    CONSTRAINT fixed_assets_cost_check CHECK (cost > 0 AND salvage_value >= 0 AND salvage_value <= cost),
+    CONSTRAINT fixed_assets_life_check CHECK (useful_life_months > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fixed_assets_org_state ON fixed_assets(organization_id, state);
+CREATE INDEX IF NOT EXISTS idx_fixed_assets_category ON fixed_assets(category_id);
+
+-- ============================================================================
+-- Depreciation schedule
+-- ============================================================================
+-- One line per month, dated at month end. move_id is the journal entry
+-- that posted the line; lines not yet posted are dropped on disposal.
+
+CREATE TABLE IF NOT EXISTS asset_depreciation_lines (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    asset_id uuid NOT NULL REFERENCES fixed_assets(id) ON DELETE CASCADE,
+    sequence integer NOT NULL,
+    period_date date NOT NULL,
+    amount numeric(15,2) NOT NULL,
+    accumulated numeric(15,2) NOT NULL,
+    book_value numeric(15,2) NOT NULL,
+    move_id uuid REFERENCES invoices(id) ON DELETE SET NULL,
+    posted_at timestamptz,
+
+    CONSTRAINT asset_depreciation_lines_period_unique UNIQUE (asset_id, period_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_depreciation_lines_due ON asset_depreciation_lines(organization_id, period_date)
+    WHERE move_id IS NULL;
+
+-- ============================================================================
+-- Transfers
+-- ============================================================================
+-- Moves between departments, locations and custodians. Transfers stay
+-- within the organization and post no entry.
+
+CREATE TABLE IF NOT EXISTS asset_transfers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    asset_id uuid NOT NULL REFERENCES fixed_assets(id) ON DELETE CASCADE,
+    transfer_date date NOT NULL,
+    from_department_id uuid REFERENCES departments(id) ON DELETE SET NULL,
+    to_department_id uuid REFERENCES departments(id) ON DELETE SET NULL,
+    from_location varchar(255),
+    to_location varchar(255),
+    from_custodian_id uuid,
+    to_custodian_id uuid,
+    note text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_transfers_asset ON asset_transfers(asset_id, transfer_date DESC);
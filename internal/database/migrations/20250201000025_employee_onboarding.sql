@@ -0,0 +1,150 @@
+-- Migration: Employee Onboarding
+-- Description: Templated onboarding and offboarding checklists with document collection, account provisioning, equipment assignments and CRM ownership transfer
+-- Version: 20250201000025
+
+-- ============================================================================
+-- Templates
+-- ============================================================================
+-- A template is the checklist started when an employee joins (onboarding)
+-- or leaves (offboarding). A template scoped to a department is preferred
+-- over one without a department when a workflow starts from an HR event.
+
+CREATE TABLE IF NOT EXISTS onboarding_templates (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    kind varchar(20) NOT NULL,
+    department_id uuid REFERENCES departments(id),
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT onboarding_templates_name_unique UNIQUE (organization_id, name),
+    CONSTRAINT onboarding_templates_kind_check CHECK (kind IN ('onboarding', 'offboarding'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_onboarding_templates_kind ON onboarding_templates(organization_id, kind) WHERE active;
+
+-- Template tasks are copied into each workflow started from the template.
+-- assignee is resolved when the workflow starts: the employee, their
+-- manager, or assignee_user_id. due_offset_days counts from the workflow's
+-- start date (the hire date, or the last day for offboarding) and may be
+-- negative. Provisioning tasks name the system whose account is granted or
+-- revoked; equipment tasks name the equipment handed over.
+
+CREATE TABLE IF NOT EXISTS onboarding_template_tasks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id uuid NOT NULL REFERENCES onboarding_templates(id) ON DELETE CASCADE,
+    sequence integer NOT NULL DEFAULT 10,
+    name varchar(255) NOT NULL,
+    description text,
+    task_type varchar(20) NOT NULL DEFAULT 'task',
+    assignee varchar(20) NOT NULL DEFAULT 'manager',
+    assignee_user_id uuid,
+    due_offset_days integer NOT NULL DEFAULT 0,
+    required boolean NOT NULL DEFAULT true,
+    provisioning_system varchar(100),
+    equipment_name varchar(255),
+
+    CONSTRAINT onboarding_template_tasks_type_check CHECK (task_type IN ('task', 'document', 'provisioning', 'equipment')),
+    CONSTRAINT onboarding_template_tasks_assignee_check CHECK (assignee IN ('employee', 'manager', 'user')),
+    CONSTRAINT onboarding_template_tasks_user_check CHECK (assignee <> 'user' OR assignee_user_id IS NOT NULL),
+    CONSTRAINT onboarding_template_tasks_system_check CHECK (task_type <> 'provisioning' OR provisioning_system IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_onboarding_template_tasks_template ON onboarding_template_tasks(template_id, sequence);
+
+-- ============================================================================
+-- Workflows
+-- ============================================================================
+-- A workflow is one run of a checklist for one employee. It completes once
+-- every required task is done or skipped; an employee has at most one open
+-- workflow of each kind. The ownership_* columns record the CRM records
+-- moved from a departing employee to their successor.
+
+CREATE TABLE IF NOT EXISTS onboarding_workflows (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id uuid NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    template_id uuid REFERENCES onboarding_templates(id) ON DELETE SET NULL,
+    kind varchar(20) NOT NULL,
+    state varchar(20) NOT NULL DEFAULT 'in_progress',
+    start_date date NOT NULL,
+    ownership_user_id uuid,
+    ownership_contacts integer NOT NULL DEFAULT 0,
+    ownership_leads integer NOT NULL DEFAULT 0,
+    ownership_activities integer NOT NULL DEFAULT 0,
+    ownership_transferred_at timestamptz,
+    completed_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT onboarding_workflows_kind_check CHECK (kind IN ('onboarding', 'offboarding')),
+    CONSTRAINT onboarding_workflows_state_check CHECK (state IN ('in_progress', 'completed', 'cancelled'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS onboarding_workflows_open_unique
+    ON onboarding_workflows(employee_id, kind) WHERE state = 'in_progress';
+CREATE INDEX IF NOT EXISTS idx_onboarding_workflows_org ON onboarding_workflows(organization_id, state, start_date);
+
+-- ============================================================================
+-- Equipment
+-- ============================================================================
+-- Equipment handed to an employee, optionally a fixed asset from the
+-- register. It stays assigned until returned; offboarding adds a return
+-- task for each piece still assigned.
+
+CREATE TABLE IF NOT EXISTS equipment_assignments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id uuid NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    serial_number varchar(100),
+    asset_id uuid REFERENCES fixed_assets(id),
+    assigned_at timestamptz NOT NULL DEFAULT now(),
+    returned_at timestamptz,
+    created_by uuid
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_assignments_employee ON equipment_assignments(employee_id) WHERE returned_at IS NULL;
+
+-- ============================================================================
+-- Workflow tasks
+-- ============================================================================
+-- Tasks are pending until done or skipped; only optional tasks can be
+-- skipped. Provisioning tasks run against a registered provisioner when the
+-- workflow starts and are failed, with the error kept, when it rejects them.
+-- Document tasks are done once a document is attached.
+
+CREATE TABLE IF NOT EXISTS onboarding_tasks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    workflow_id uuid NOT NULL REFERENCES onboarding_workflows(id) ON DELETE CASCADE,
+    sequence integer NOT NULL DEFAULT 10,
+    name varchar(255) NOT NULL,
+    description text,
+    task_type varchar(20) NOT NULL DEFAULT 'task',
+    assignee_user_id uuid,
+    due_date date,
+    required boolean NOT NULL DEFAULT true,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    provisioning_system varchar(100),
+    equipment_name varchar(255),
+    equipment_assignment_id uuid REFERENCES equipment_assignments(id) ON DELETE SET NULL,
+    document_url varchar(1000),
+    notes text,
+    error text,
+    completed_at timestamptz,
+    completed_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT onboarding_tasks_type_check CHECK (task_type IN ('task', 'document', 'provisioning', 'equipment')),
+    CONSTRAINT onboarding_tasks_status_check CHECK (status IN ('pending', 'done', 'skipped', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_onboarding_tasks_workflow ON onboarding_tasks(workflow_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_onboarding_tasks_assignee ON onboarding_tasks(assignee_user_id, due_date) WHERE status IN ('pending', 'failed');
@@ -0,0 +1,50 @@
+-- Migration: Lead Stage History
+-- Description: Records every lead stage change with its actor and time spent in the previous stage, and the stage transitions each pipeline allows
+-- Version: 20250201000026
+
+-- ============================================================================
+-- Stage history
+-- ============================================================================
+-- One row per stage change. duration_seconds is how long the lead sat in
+-- from_stage_id before the move, measured from its previous stage change
+-- (or its creation for the first move). Stage velocity is computed from it.
+
+CREATE TABLE IF NOT EXISTS lead_stage_history (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE SET NULL,
+    from_stage_id uuid REFERENCES lead_stages(id) ON DELETE SET NULL,
+    to_stage_id uuid REFERENCES lead_stages(id) ON DELETE SET NULL,
+    changed_by uuid,
+    changed_at timestamptz NOT NULL DEFAULT now(),
+    duration_seconds bigint NOT NULL DEFAULT 0,
+    note text,
+
+    CONSTRAINT lead_stage_history_duration_check CHECK (duration_seconds >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_stage_history_lead ON lead_stage_history(lead_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_lead_stage_history_from_stage ON lead_stage_history(organization_id, from_stage_id, changed_at);
+
+-- ============================================================================
+-- Allowed transitions
+-- ============================================================================
+-- The moves a pipeline allows. A sales team's rows apply to its leads; rows
+-- without a team are the organization default. A pipeline without any rows
+-- allows every move.
+
+CREATE TABLE IF NOT EXISTS lead_stage_transitions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE CASCADE,
+    from_stage_id uuid NOT NULL REFERENCES lead_stages(id) ON DELETE CASCADE,
+    to_stage_id uuid NOT NULL REFERENCES lead_stages(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT lead_stage_transitions_self_check CHECK (from_stage_id <> to_stage_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_stage_transitions_unique
+    ON lead_stage_transitions(organization_id, COALESCE(team_id, '00000000-0000-0000-0000-000000000000'::uuid), from_stage_id, to_stage_id);
@@ -0,0 +1,157 @@
+-- Migration: Recruitment
+-- Description: Job postings and candidate pipelines with stages, stage history, documents, interviews and careers page applications
+-- Version: 20250201000027
+
+-- ============================================================================
+-- Job postings
+-- ============================================================================
+-- A posting advertises an opening, usually of a job position. Published
+-- postings are listed on the organization's careers page under their slug
+-- and accept applications until closed or past closes_at.
+
+CREATE TABLE IF NOT EXISTS job_postings (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    job_position_id uuid REFERENCES job_positions(id) ON DELETE SET NULL,
+    department_id uuid REFERENCES departments(id),
+    title varchar(255) NOT NULL,
+    slug varchar(100) NOT NULL,
+    description text,
+    requirements text,
+    location varchar(255),
+    employment_type varchar(50),
+    state varchar(20) NOT NULL DEFAULT 'draft',
+    recruiter_id uuid,
+    published_at timestamptz,
+    closes_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT job_postings_slug_unique UNIQUE (organization_id, slug),
+    CONSTRAINT job_postings_state_check CHECK (state IN ('draft', 'published', 'closed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_postings_state ON job_postings(organization_id, state);
+
+-- ============================================================================
+-- Stages
+-- ============================================================================
+-- Stages without a posting are the organization's default pipeline; a
+-- posting with stages of its own uses those instead. Moving a candidate
+-- to a hired stage marks them hired.
+
+CREATE TABLE IF NOT EXISTS recruitment_stages (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    job_posting_id uuid REFERENCES job_postings(id) ON DELETE CASCADE,
+    name varchar(100) NOT NULL,
+    sequence integer NOT NULL DEFAULT 10,
+    fold boolean NOT NULL DEFAULT false,
+    hired boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recruitment_stages_pipeline ON recruitment_stages(organization_id, job_posting_id, sequence);
+
+-- ============================================================================
+-- Candidates
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS candidates (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    job_posting_id uuid NOT NULL REFERENCES job_postings(id) ON DELETE CASCADE,
+    stage_id uuid REFERENCES recruitment_stages(id),
+    name varchar(255) NOT NULL,
+    email varchar(255) NOT NULL,
+    phone varchar(50),
+    source varchar(50) NOT NULL DEFAULT 'manual',
+    recruiter_id uuid,
+    rating integer NOT NULL DEFAULT 0,
+    status varchar(20) NOT NULL DEFAULT 'active',
+    refuse_reason text,
+    cover_letter text,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    date_last_stage_update timestamptz,
+    hired_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT candidates_rating_check CHECK (rating BETWEEN 0 AND 5),
+    CONSTRAINT candidates_status_check CHECK (status IN ('active', 'hired', 'refused', 'withdrawn'))
+);
+
+-- One application per person and posting, so careers page resubmissions
+-- do not create duplicates
+CREATE UNIQUE INDEX IF NOT EXISTS candidates_posting_email_unique ON candidates(job_posting_id, lower(email));
+CREATE INDEX IF NOT EXISTS idx_candidates_stage ON candidates(organization_id, job_posting_id, stage_id);
+CREATE INDEX IF NOT EXISTS idx_candidates_recruiter ON candidates(organization_id, recruiter_id) WHERE status = 'active';
+
+-- Stage changes with the time spent in the previous stage, as for leads
+CREATE TABLE IF NOT EXISTS candidate_stage_history (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    candidate_id uuid NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+    from_stage_id uuid REFERENCES recruitment_stages(id) ON DELETE SET NULL,
+    to_stage_id uuid REFERENCES recruitment_stages(id) ON DELETE SET NULL,
+    changed_by uuid,
+    changed_at timestamptz NOT NULL DEFAULT now(),
+    duration_seconds bigint NOT NULL DEFAULT 0,
+    note text
+);
+
+CREATE INDEX IF NOT EXISTS idx_candidate_stage_history_candidate ON candidate_stage_history(candidate_id, changed_at);
+
+-- Resumes, cover letters and other files, stored elsewhere and linked by URL
+CREATE TABLE IF NOT EXISTS candidate_documents (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    candidate_id uuid NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+    kind varchar(20) NOT NULL DEFAULT 'other',
+    name varchar(255) NOT NULL,
+    url text NOT NULL,
+    uploaded_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT candidate_documents_kind_check CHECK (kind IN ('resume', 'cover_letter', 'portfolio', 'other'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_candidate_documents_candidate ON candidate_documents(candidate_id);
+
+-- ============================================================================
+-- Interviews
+-- ============================================================================
+-- Interviews are scheduled within the organization's business hours; an
+-- interviewer cannot be booked into two scheduled interviews at once.
+
+CREATE TABLE IF NOT EXISTS interviews (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    candidate_id uuid NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+    title varchar(255) NOT NULL,
+    interviewer_ids uuid[] NOT NULL DEFAULT '{}',
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    location varchar(255),
+    meeting_url text,
+    state varchar(20) NOT NULL DEFAULT 'scheduled',
+    feedback text,
+    rating integer,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT interviews_period_check CHECK (ends_at > starts_at),
+    CONSTRAINT interviews_rating_check CHECK (rating IS NULL OR rating BETWEEN 0 AND 5),
+    CONSTRAINT interviews_state_check CHECK (state IN ('scheduled', 'completed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_interviews_candidate ON interviews(candidate_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_interviews_schedule ON interviews(organization_id, starts_at) WHERE state = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_interviews_interviewers ON interviews USING gin(interviewer_ids);
@@ -0,0 +1,19 @@
+-- Migration: Lead Conversion
+-- Description: Records when a lead was converted to an opportunity and links quotations to the opportunity they were raised from
+-- Version: 20250201000028
+
+-- ============================================================================
+-- Leads
+-- ============================================================================
+-- A converted lead keeps its row, and with it its activities, attachments
+-- and stage history; it becomes an opportunity linked to a contact.
+
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS date_conversion timestamptz;
+
+-- ============================================================================
+-- Sales orders
+-- ============================================================================
+
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS opportunity_id uuid REFERENCES leads(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sales_orders_opportunity ON sales_orders(opportunity_id) WHERE opportunity_id IS NOT NULL;
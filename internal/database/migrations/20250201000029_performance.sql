@@ -0,0 +1,134 @@
+-- Migration: Performance
+-- Description: Employee and department goals with key results and check-ins, review templates, review cycles and manager, self and peer reviews
+-- Version: 20250201000029
+
+-- ============================================================================
+-- Goals
+-- ============================================================================
+-- A goal belongs to an employee or a department and may contribute to a
+-- parent goal. Manual key results move with check-ins; sales key results
+-- are measured from commission credits or won leads of the goal's owners.
+
+CREATE TABLE IF NOT EXISTS goals (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id uuid REFERENCES employees(id) ON DELETE CASCADE,
+    department_id uuid REFERENCES departments(id) ON DELETE CASCADE,
+    parent_id uuid REFERENCES goals(id) ON DELETE SET NULL,
+    title varchar(255) NOT NULL,
+    description text,
+    period_start date NOT NULL,
+    period_end date NOT NULL,
+    state varchar(20) NOT NULL DEFAULT 'active',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT goals_owner_check CHECK ((employee_id IS NULL) <> (department_id IS NULL)),
+    CONSTRAINT goals_period_check CHECK (period_end >= period_start),
+    CONSTRAINT goals_state_check CHECK (state IN ('active', 'completed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_employee ON goals(organization_id, employee_id, period_start);
+CREATE INDEX IF NOT EXISTS idx_goals_department ON goals(organization_id, department_id, period_start);
+CREATE INDEX IF NOT EXISTS idx_goals_parent ON goals(parent_id) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS goal_key_results (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    goal_id uuid NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    title varchar(255) NOT NULL,
+    metric varchar(20) NOT NULL DEFAULT 'manual',
+    unit varchar(50),
+    start_value numeric(15,2) NOT NULL DEFAULT 0,
+    target_value numeric(15,2) NOT NULL,
+    current_value numeric(15,2) NOT NULL DEFAULT 0,
+    sequence integer NOT NULL DEFAULT 10,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT goal_key_results_metric_check CHECK (metric IN ('manual', 'sales_credit', 'won_revenue')),
+    CONSTRAINT goal_key_results_target_check CHECK (target_value <> start_value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_key_results_goal ON goal_key_results(goal_id, sequence);
+
+CREATE TABLE IF NOT EXISTS goal_check_ins (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    goal_id uuid NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    key_result_id uuid NOT NULL REFERENCES goal_key_results(id) ON DELETE CASCADE,
+    value numeric(15,2) NOT NULL,
+    confidence integer,
+    note text,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT goal_check_ins_confidence_check CHECK (confidence IS NULL OR confidence BETWEEN 0 AND 10)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_check_ins_goal ON goal_check_ins(goal_id, created_at);
+
+-- ============================================================================
+-- Reviews
+-- ============================================================================
+-- Opening a cycle creates a self review and a manager review for every
+-- active employee in scope; peer reviews are requested per employee.
+-- Questions and answers are stored as JSON arrays.
+
+CREATE TABLE IF NOT EXISTS review_templates (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    description text,
+    questions jsonb NOT NULL DEFAULT '[]'::jsonb,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT review_templates_name_unique UNIQUE (organization_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS review_cycles (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    template_id uuid NOT NULL REFERENCES review_templates(id),
+    department_id uuid REFERENCES departments(id),
+    period_start date NOT NULL,
+    period_end date NOT NULL,
+    due_date date NOT NULL,
+    state varchar(20) NOT NULL DEFAULT 'draft',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT review_cycles_period_check CHECK (period_end >= period_start),
+    CONSTRAINT review_cycles_state_check CHECK (state IN ('draft', 'open', 'closed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_cycles_state ON review_cycles(organization_id, state);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    cycle_id uuid NOT NULL REFERENCES review_cycles(id) ON DELETE CASCADE,
+    employee_id uuid NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    reviewer_user_id uuid NOT NULL,
+    relationship varchar(20) NOT NULL,
+    state varchar(20) NOT NULL DEFAULT 'pending',
+    answers jsonb NOT NULL DEFAULT '[]'::jsonb,
+    submitted_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT reviews_unique UNIQUE (cycle_id, employee_id, reviewer_user_id, relationship),
+    CONSTRAINT reviews_relationship_check CHECK (relationship IN ('self', 'manager', 'peer')),
+    CONSTRAINT reviews_state_check CHECK (state IN ('pending', 'submitted'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_reviewer ON reviews(organization_id, reviewer_user_id, state);
+CREATE INDEX IF NOT EXISTS idx_reviews_employee ON reviews(cycle_id, employee_id);
@@ -0,0 +1,81 @@
+-- Migration: Training
+-- Description: Certification types, employee certifications with expiry alerts, and the certifications required to be assigned QC inspections and hazmat routes
+-- Version: 20250201000030
+
+-- ============================================================================
+-- Certification types
+-- ============================================================================
+-- A certification type is a qualification such as a forklift licence, a
+-- hazmat endorsement or an inspector qualification. New certifications of a
+-- type expire after its validity, and their holders are alerted alert_days
+-- before they do.
+
+CREATE TABLE IF NOT EXISTS certification_types (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    code varchar(50) NOT NULL,
+    name varchar(255) NOT NULL,
+    category varchar(20) NOT NULL DEFAULT 'other',
+    validity_months integer,
+    alert_days integer NOT NULL DEFAULT 30,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT certification_types_code_key UNIQUE (organization_id, code),
+    CONSTRAINT certification_types_category_check CHECK (category IN ('forklift', 'hazmat', 'inspector', 'safety', 'other')),
+    CONSTRAINT certification_types_validity_check CHECK (validity_months IS NULL OR validity_months > 0),
+    CONSTRAINT certification_types_alert_days_check CHECK (alert_days >= 0)
+);
+
+-- ============================================================================
+-- Employee certifications
+-- ============================================================================
+-- Renewing a certification records a new one; the old one stays as history.
+-- alerted_at is set once the expiry alert of the certification was raised.
+
+CREATE TABLE IF NOT EXISTS employee_certifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id uuid NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    certification_type_id uuid NOT NULL REFERENCES certification_types(id) ON DELETE RESTRICT,
+    certificate_number varchar(100),
+    issuer varchar(255),
+    issued_on date NOT NULL,
+    expires_on date,
+    notes text,
+    revoked_at timestamptz,
+    revoked_reason text,
+    alerted_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT employee_certifications_dates_check CHECK (expires_on IS NULL OR expires_on >= issued_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_employee_certifications_employee
+    ON employee_certifications(organization_id, employee_id, certification_type_id);
+CREATE INDEX IF NOT EXISTS idx_employee_certifications_expiry
+    ON employee_certifications(expires_on)
+    WHERE revoked_at IS NULL AND alerted_at IS NULL AND expires_on IS NOT NULL;
+
+-- ============================================================================
+-- Activity requirements
+-- ============================================================================
+-- Employees are only assigned an activity while they hold an active
+-- certification of every type it requires.
+
+CREATE TABLE IF NOT EXISTS certification_requirements (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    activity varchar(30) NOT NULL,
+    certification_type_id uuid NOT NULL REFERENCES certification_types(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    PRIMARY KEY (organization_id, activity, certification_type_id),
+    CONSTRAINT certification_requirements_activity_check CHECK (activity IN ('qc_inspection', 'hazmat_route'))
+);
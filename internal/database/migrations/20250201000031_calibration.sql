@@ -0,0 +1,81 @@
+-- Migration: Calibration
+-- Description: Measuring equipment with calibration intervals, calibration history with certificates, due-date alerts, and the instrument used on each QC inspection item
+-- Version: 20250201000031
+
+-- ============================================================================
+-- Equipment
+-- ============================================================================
+-- Instruments and tools that must be calibrated every calibration_interval_days.
+-- last_result and next_due_on follow the latest calibration; alerted_due_on is
+-- the due date the last due alert was raised for, so each due date alerts once.
+
+CREATE TABLE IF NOT EXISTS calibration_equipment (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    code varchar(50) NOT NULL,
+    name varchar(255) NOT NULL,
+    equipment_type varchar(100),
+    manufacturer varchar(255),
+    model varchar(255),
+    serial_number varchar(100),
+    location varchar(255),
+    asset_id uuid REFERENCES fixed_assets(id) ON DELETE SET NULL,
+    calibration_interval_days integer NOT NULL,
+    alert_days integer NOT NULL DEFAULT 14,
+    status varchar(20) NOT NULL DEFAULT 'active',
+    last_calibrated_on date,
+    last_result varchar(20),
+    next_due_on date,
+    alerted_due_on date,
+    notes text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT calibration_equipment_code_key UNIQUE (organization_id, code),
+    CONSTRAINT calibration_equipment_status_check CHECK (status IN ('active', 'out_of_service', 'retired')),
+    CONSTRAINT calibration_equipment_interval_check CHECK (calibration_interval_days > 0),
+    CONSTRAINT calibration_equipment_alert_days_check CHECK (alert_days >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_calibration_equipment_due
+    ON calibration_equipment(next_due_on)
+    WHERE status = 'active';
+
+-- ============================================================================
+-- Calibration history
+-- ============================================================================
+-- One row per calibration performed. A failed calibration takes the
+-- equipment out of service until it passes again.
+
+CREATE TABLE IF NOT EXISTS equipment_calibrations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    equipment_id uuid NOT NULL REFERENCES calibration_equipment(id) ON DELETE CASCADE,
+    calibrated_on date NOT NULL,
+    result varchar(20) NOT NULL,
+    performed_by varchar(255),
+    certificate_number varchar(100),
+    certificate_url text,
+    next_due_on date,
+    notes text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT equipment_calibrations_result_check CHECK (result IN ('pass', 'adjusted', 'fail'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_calibrations_equipment
+    ON equipment_calibrations(equipment_id, calibrated_on DESC);
+
+-- ============================================================================
+-- Instrument used on inspection items
+-- ============================================================================
+
+ALTER TABLE quality_control_inspection_items
+    ADD COLUMN IF NOT EXISTS equipment_id uuid REFERENCES calibration_equipment(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_qc_inspection_items_equipment
+    ON quality_control_inspection_items(equipment_id)
+    WHERE equipment_id IS NOT NULL;
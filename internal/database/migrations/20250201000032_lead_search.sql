@@ -0,0 +1,29 @@
+-- Migration: Lead Full-Text Search
+-- Description: Weighted tsvector search column on leads backing ranked lead search
+-- Version: 20250201000032
+
+-- ============================================================================
+-- Search Vector
+-- ============================================================================
+-- The 'simple' configuration keeps names, emails and websites intact instead
+-- of stemming them. Phone numbers are also indexed as bare digits so that
+-- "555-0100" and "(555) 0100" find the same lead. Weights rank name matches
+-- above contact details, and contact details above the description.
+
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(contact_name, '') || ' ' || coalesce(email, '')), 'B') ||
+        setweight(to_tsvector('simple',
+            coalesce(phone, '') || ' ' || coalesce(mobile, '') || ' ' ||
+            regexp_replace(coalesce(phone, ''), '\D', '', 'g') || ' ' ||
+            regexp_replace(coalesce(mobile, ''), '\D', '', 'g') || ' ' ||
+            coalesce(website, '')), 'C') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+    ) STORED;
+
+-- ============================================================================
+-- Indexes
+-- ============================================================================
+
+CREATE INDEX IF NOT EXISTS idx_leads_search_vector ON leads USING gin(search_vector) WHERE deleted_at IS NULL;
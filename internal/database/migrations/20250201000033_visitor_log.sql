@@ -0,0 +1,61 @@
+-- Migration: Visitor Log
+-- Description: Visitor pre-registration, check-in and check-out at offices and warehouses, with badges printed from document templates
+-- Version: 20250201000033
+
+-- ============================================================================
+-- Visits
+-- ============================================================================
+-- A visit is pre-registered, or registered at reception for walk-ins, and
+-- then checked in and out. Visits are never deleted so the log can be
+-- audited; a visit that does not happen is cancelled instead. The badge
+-- number is only unique among the visitors currently on site, as badges are
+-- handed back and reissued.
+
+CREATE TABLE IF NOT EXISTS visits (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    visitor_name varchar(255) NOT NULL,
+    visitor_company varchar(255),
+    visitor_email varchar(255),
+    visitor_phone varchar(50),
+    host_employee_id uuid NOT NULL REFERENCES employees(id) ON DELETE RESTRICT,
+    warehouse_id uuid REFERENCES warehouses(id) ON DELETE SET NULL,
+    purpose text,
+    vehicle_plate varchar(20),
+    expected_at timestamptz NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'expected',
+    badge_number varchar(50),
+    id_document varchar(100),
+    safety_briefed boolean NOT NULL DEFAULT false,
+    checked_in_at timestamptz,
+    checked_in_by uuid,
+    checked_out_at timestamptz,
+    checked_out_by uuid,
+    badge_returned boolean,
+    cancelled_at timestamptz,
+    cancelled_by uuid,
+    notes text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT visits_status_check CHECK (status IN ('expected', 'checked_in', 'checked_out', 'cancelled')),
+    CONSTRAINT visits_checked_in_check CHECK (status IN ('expected', 'cancelled') OR checked_in_at IS NOT NULL),
+    CONSTRAINT visits_checked_out_check CHECK (status <> 'checked_out' OR checked_out_at >= checked_in_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_visits_org_expected ON visits(organization_id, expected_at DESC);
+CREATE INDEX IF NOT EXISTS idx_visits_on_site ON visits(organization_id, warehouse_id) WHERE status = 'checked_in';
+CREATE INDEX IF NOT EXISTS idx_visits_host ON visits(host_employee_id);
+CREATE UNIQUE INDEX IF NOT EXISTS visits_badge_on_site_key
+    ON visits(organization_id, badge_number)
+    WHERE status = 'checked_in' AND badge_number IS NOT NULL;
+
+-- ============================================================================
+-- Visitor badges
+-- ============================================================================
+
+ALTER TABLE document_templates DROP CONSTRAINT IF EXISTS document_templates_kind_check;
+ALTER TABLE document_templates ADD CONSTRAINT document_templates_kind_check
+    CHECK (kind IN ('quote', 'invoice', 'proof_of_delivery', 'qc_certificate', 'route_manifest', 'visitor_badge', 'other'));
@@ -0,0 +1,77 @@
+-- Migration: Compliance Audit Trail
+-- Description: Security event log (logins, impersonation), audit trail retention independent of data retention policies, and the hash-chained audit exports taken for compliance reviews
+-- Version: 20250201000034
+
+-- ============================================================================
+-- Security Events
+-- ============================================================================
+-- Sign-ins, failed sign-ins and impersonation sessions. For impersonation,
+-- user_id is the impersonated user and actor_user_id the administrator acting
+-- as them. Failed sign-ins are only recorded for known users, as an unknown
+-- email cannot be attributed to an organization.
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    event_type varchar(50) NOT NULL,
+    user_id uuid,
+    actor_user_id uuid,
+    ip_address inet,
+    user_agent text,
+    metadata jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT security_events_type_check CHECK (event_type IN (
+        'login.succeeded', 'login.failed', 'impersonation.started', 'impersonation.ended'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_org_created ON security_events(organization_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_security_events_user ON security_events(user_id, created_at DESC);
+
+-- ============================================================================
+-- Audit Trail Retention
+-- ============================================================================
+-- Kept apart from retention_policies so that shortening data retention never
+-- shortens the audit trail. Rows only exist for organizations that override
+-- the default of 7 years.
+
+CREATE TABLE IF NOT EXISTS compliance_audit_settings (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    retention_days integer NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT compliance_audit_settings_days_check CHECK (retention_days >= 365)
+);
+
+-- ============================================================================
+-- Audit Exports
+-- ============================================================================
+-- Each export is a chain of records where every record's hash covers the
+-- previous one. The head hash is kept here so a copy handed to an auditor
+-- can later be checked against what was exported.
+
+CREATE TABLE IF NOT EXISTS audit_exports (
+    id uuid PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    period_start timestamptz NOT NULL,
+    period_end timestamptz NOT NULL,
+    record_count integer NOT NULL,
+    head_hash varchar(64) NOT NULL,
+    exported_by uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT audit_exports_period_check CHECK (period_end > period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_exports_org_created ON audit_exports(organization_id, created_at DESC);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'compliance', 'read'),
+    ('p', 'role:admin', 'compliance', 'manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,69 @@
+-- Migration: Lead SLA
+-- Description: Response-time targets for leads per stage and priority, and the SLA clock of every lead stage entry they apply to, flagged when breached and escalated through the assignment rules
+-- Version: 20250201000035
+
+-- ============================================================================
+-- SLA Policies
+-- ============================================================================
+-- How quickly a lead must be responded to after it enters a stage. A policy
+-- without a stage or priority applies to every stage or priority; the most
+-- specific active policy wins, stage before priority. response_minutes are
+-- counted in the business hours of the lead's team.
+
+CREATE TABLE IF NOT EXISTS lead_sla_policies (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    stage_id uuid REFERENCES lead_stages(id) ON DELETE CASCADE,
+    priority varchar(20),
+    response_minutes integer NOT NULL,
+    escalate boolean NOT NULL DEFAULT true,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT lead_sla_policies_priority_check CHECK (priority IN ('low', 'medium', 'high', 'urgent')),
+    CONSTRAINT lead_sla_policies_response_check CHECK (response_minutes > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_sla_policies_unique
+    ON lead_sla_policies(organization_id, COALESCE(stage_id, '00000000-0000-0000-0000-000000000000'::uuid), COALESCE(priority, ''));
+
+-- ============================================================================
+-- SLA Clocks
+-- ============================================================================
+-- One row per lead stage entry a policy applied to, started when the lead
+-- was created or last changed stage; entries older than the policy are not
+-- timed. Logging an activity on the lead, moving
+-- it to another stage or closing it is a response. A clock is breached when
+-- the lead was not responded to by due_at; breached clocks of escalating
+-- policies are reassigned through the assignment rules.
+
+CREATE TABLE IF NOT EXISTS lead_sla_clocks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    policy_id uuid REFERENCES lead_sla_policies(id) ON DELETE SET NULL,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE SET NULL,
+    stage_id uuid REFERENCES lead_stages(id) ON DELETE SET NULL,
+    priority varchar(20) NOT NULL,
+    assigned_to uuid,
+    escalate boolean NOT NULL DEFAULT false,
+    started_at timestamptz NOT NULL,
+    due_at timestamptz NOT NULL,
+    responded_at timestamptz,
+    breached_at timestamptz,
+    escalated_at timestamptz,
+    escalated_to uuid,
+    -- The assignment outcome, e.g. reassignment or no_assignee_found
+    escalation_result varchar(50),
+
+    CONSTRAINT lead_sla_clocks_due_check CHECK (due_at >= started_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_sla_clocks_entry ON lead_sla_clocks(lead_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_lead_sla_clocks_open ON lead_sla_clocks(due_at) WHERE responded_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_lead_sla_clocks_breached ON lead_sla_clocks(organization_id, breached_at) WHERE breached_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_lead_sla_clocks_org_started ON lead_sla_clocks(organization_id, started_at);
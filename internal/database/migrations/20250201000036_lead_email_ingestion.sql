@@ -0,0 +1,69 @@
+-- Migration: Lead Email Ingestion
+-- Description: Inbound mailboxes whose provider webhooks (SendGrid, Mailgun, SES) turn received emails into leads, and the received messages threaded on each lead's activity timeline
+-- Version: 20250201000036
+
+-- ============================================================================
+-- Inbound Mailboxes
+-- ============================================================================
+-- An address routed to the CRM through an email provider. The provider posts
+-- each received email to /public/v1/inbound-email/<token>; the token is the
+-- webhook's secret. Mailgun posts are also checked against signing_key when
+-- one is set. Leads created from the mailbox take its source and team.
+
+CREATE TABLE IF NOT EXISTS lead_email_inboxes (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    address varchar(255),
+    provider varchar(20) NOT NULL,
+    token varchar(64) NOT NULL,
+    signing_key text,
+    source_id uuid REFERENCES lead_sources(id) ON DELETE SET NULL,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE SET NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT lead_email_inboxes_provider_check CHECK (provider IN ('sendgrid', 'mailgun', 'ses'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_email_inboxes_token ON lead_email_inboxes(token);
+CREATE INDEX IF NOT EXISTS idx_lead_email_inboxes_org ON lead_email_inboxes(organization_id);
+
+-- ============================================================================
+-- Received Messages
+-- ============================================================================
+-- Every email attached to a lead, with the email activity it appears as on
+-- the lead's timeline. A reply, recognised by its In-Reply-To or References
+-- headers, joins the thread and lead of the message it answers; other emails
+-- start a thread on the sender's open lead or on a new lead. Provider retries
+-- are recognised by Message-ID. Inbound emails are not responses to a lead,
+-- so the SLA worker ignores their activities.
+
+CREATE TABLE IF NOT EXISTS lead_email_messages (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    inbox_id uuid REFERENCES lead_email_inboxes(id) ON DELETE SET NULL,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    activity_id uuid REFERENCES activities(id) ON DELETE SET NULL,
+    thread_id uuid NOT NULL,
+    message_id varchar(998),
+    in_reply_to varchar(998),
+    "references" text[] NOT NULL DEFAULT '{}',
+    from_email varchar(255) NOT NULL,
+    from_name varchar(255),
+    to_emails text[] NOT NULL DEFAULT '{}',
+    subject text NOT NULL DEFAULT '',
+    body_text text,
+    body_html text,
+    received_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_email_messages_message_id
+    ON lead_email_messages(organization_id, message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_lead_email_messages_lead ON lead_email_messages(lead_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_lead_email_messages_thread ON lead_email_messages(thread_id);
+CREATE INDEX IF NOT EXISTS idx_lead_email_messages_activity ON lead_email_messages(activity_id);
@@ -0,0 +1,100 @@
+-- Migration: Tenant Offboarding
+-- Description: Full-tenant export archives with expiring download links, and the verified deletion of a leaving organization's data with a deletion certificate
+-- Version: 20250201000037
+
+-- ============================================================================
+-- Tenant Exports
+-- ============================================================================
+-- An export is a zip archive of every organization-scoped row, as JSON lines
+-- grouped by module, plus the organization's attachment files. Archives are
+-- built in the background and removed from storage once they expire.
+
+CREATE TABLE IF NOT EXISTS tenant_exports (
+    id uuid PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    storage_key varchar(500),
+    size_bytes bigint,
+    sha256 varchar(64),
+    manifest jsonb,
+    error text,
+    requested_by uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz,
+    expires_at timestamptz,
+    first_downloaded_at timestamptz,
+
+    CONSTRAINT tenant_exports_status_check CHECK (status IN (
+        'pending', 'running', 'completed', 'failed', 'expired'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_exports_org_created ON tenant_exports(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tenant_exports_pending ON tenant_exports(created_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_tenant_exports_expires ON tenant_exports(expires_at) WHERE status = 'completed';
+
+-- At most one export of an organization is queued or running at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_exports_one_active
+    ON tenant_exports(organization_id) WHERE status IN ('pending', 'running');
+
+-- ============================================================================
+-- Export Download Links
+-- ============================================================================
+-- Only the SHA-256 of a link's token is stored; the token itself is shown
+-- once, when the link is issued.
+
+CREATE TABLE IF NOT EXISTS tenant_export_links (
+    id uuid PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    export_id uuid NOT NULL REFERENCES tenant_exports(id) ON DELETE CASCADE,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    expires_at timestamptz NOT NULL,
+    created_by uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    download_count integer NOT NULL DEFAULT 0,
+    last_downloaded_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_export_links_export ON tenant_export_links(export_id);
+
+-- ============================================================================
+-- Tenant Deletions
+-- ============================================================================
+-- A deletion removes every organization-scoped row and attachment file, then
+-- counts what remains. The signed certificate it issues is kept here, with
+-- the organization row itself, after everything else is gone.
+
+CREATE TABLE IF NOT EXISTS tenant_deletions (
+    id uuid PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    export_id uuid NOT NULL REFERENCES tenant_exports(id),
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    requested_by uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz,
+    certificate jsonb,
+    error text,
+
+    CONSTRAINT tenant_deletions_status_check CHECK (status IN (
+        'pending', 'running', 'completed', 'failed'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_deletions_pending ON tenant_deletions(created_at) WHERE status IN ('pending', 'running');
+
+-- At most one deletion of an organization is queued, running or done
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_deletions_one_active
+    ON tenant_deletions(organization_id) WHERE status <> 'failed';
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'offboarding', 'export'),
+    ('p', 'role:admin', 'offboarding', 'delete')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,29 @@
+-- Migration: Scheduled Job Runs
+-- Description: Ledger of scheduled background job runs, so each period of a job runs once across all server instances
+-- Version: 20250201000038
+
+-- ============================================================================
+-- Scheduled Job Runs
+-- ============================================================================
+-- One row per job and period slot. Only the elected leader starts runs, and
+-- a slot that completed is skipped by every later leader; a failed slot is
+-- retried a limited number of times. Completed rows are pruned by the
+-- scheduler after a week.
+
+CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+    job_name varchar(100) NOT NULL,
+    slot timestamptz NOT NULL,
+    status varchar(20) NOT NULL,
+    attempts integer NOT NULL DEFAULT 1,
+    instance_id varchar(255) NOT NULL,
+    error text,
+    started_at timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz,
+
+    PRIMARY KEY (job_name, slot),
+    CONSTRAINT scheduled_job_runs_status_check CHECK (status IN (
+        'running', 'completed', 'failed'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_job_runs_failed ON scheduled_job_runs(slot DESC) WHERE status = 'failed';
@@ -0,0 +1,50 @@
+-- Migration: Lead Table Consolidation
+-- Description: Makes leads the only lead table, folding in any rows written to a leads_enhanced table by earlier builds
+-- Version: 20250201000039
+
+-- ============================================================================
+-- Fold leads_enhanced into leads
+-- ============================================================================
+-- Earlier builds created leads in leads but read, updated and deleted them
+-- through leads_enhanced. Where that table exists, its rows are copied into
+-- leads; a lead present in both keeps whichever copy was updated last, since
+-- updates and deletions only ever reached leads_enhanced. Only columns both
+-- tables share are copied, and generated columns are left to be recomputed.
+-- The old table is kept as leads_enhanced_retired for inspection rather
+-- than dropped; a view of that name is simply dropped.
+
+DO $$
+DECLARE
+    shared_columns text;
+    update_columns text;
+BEGIN
+    IF to_regclass('public.leads_enhanced') IS NULL THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM pg_views WHERE schemaname = 'public' AND viewname = 'leads_enhanced') THEN
+        DROP VIEW public.leads_enhanced;
+        RETURN;
+    END IF;
+
+    SELECT
+        string_agg(quote_ident(e.column_name), ', ' ORDER BY l.ordinal_position),
+        string_agg(format('%1$I = EXCLUDED.%1$I', e.column_name), ', ' ORDER BY l.ordinal_position)
+            FILTER (WHERE e.column_name <> 'id')
+    INTO shared_columns, update_columns
+    FROM information_schema.columns e
+    JOIN information_schema.columns l
+        ON l.table_schema = 'public' AND l.table_name = 'leads' AND l.column_name = e.column_name
+    WHERE e.table_schema = 'public'
+      AND e.table_name = 'leads_enhanced'
+      AND l.is_generated = 'NEVER';
+
+    EXECUTE format(
+        'INSERT INTO leads (%1$s) SELECT %1$s FROM leads_enhanced
+         ON CONFLICT (id) DO UPDATE SET %2$s
+         WHERE EXCLUDED.updated_at > leads.updated_at',
+        shared_columns, update_columns
+    );
+
+    ALTER TABLE public.leads_enhanced RENAME TO leads_enhanced_retired;
+END $$;
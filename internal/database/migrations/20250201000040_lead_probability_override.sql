@@ -0,0 +1,40 @@
+-- Migration: Lead Probability Override
+-- Description: Keeps the stage-driven and the manually set probability of each lead apart, so stage moves only update leads nobody overrode
+-- Version: 20250201000040
+
+-- ============================================================================
+-- Stage default probability
+-- ============================================================================
+-- A stage without a probability leaves the probability of leads moving into
+-- it unchanged. Existing stages keep the probability they have.
+
+ALTER TABLE lead_stages ALTER COLUMN probability DROP DEFAULT;
+
+-- ============================================================================
+-- Lead probabilities
+-- ============================================================================
+-- system_probability is the default of the last stage the lead moved to and
+-- manual_probability the one a user set. probability stays the effective
+-- value: the manual one while probability_overridden is set, the system one
+-- otherwise. Closing a lead sets probability to 0 or 100 but keeps both, so
+-- each can be scored against the outcome.
+
+ALTER TABLE leads
+    ADD COLUMN IF NOT EXISTS system_probability integer,
+    ADD COLUMN IF NOT EXISTS manual_probability integer,
+    ADD COLUMN IF NOT EXISTS probability_overridden boolean NOT NULL DEFAULT false;
+
+ALTER TABLE leads
+    ADD CONSTRAINT leads_system_probability_check CHECK (system_probability BETWEEN 0 AND 100),
+    ADD CONSTRAINT leads_manual_probability_check CHECK (manual_probability BETWEEN 0 AND 100),
+    ADD CONSTRAINT leads_probability_overridden_check CHECK (probability_overridden = (manual_probability IS NOT NULL));
+
+-- Stage moves always set the stage probability until now, so existing leads
+-- start from the default of their current stage and are not overridden
+UPDATE leads l
+SET system_probability = s.probability
+FROM lead_stages s
+WHERE s.id = l.stage_id AND s.probability IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_leads_closed_probabilities ON leads(organization_id, date_closed)
+    WHERE won_status IN ('won', 'lost') AND deleted_at IS NULL;
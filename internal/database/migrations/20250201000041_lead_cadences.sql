@@ -0,0 +1,95 @@
+-- Migration: Lead Cadences
+-- Description: Follow-up cadences per lead source and stage, the enrollment of every lead entering one with the activities it created, and whether each was done or skipped
+-- Version: 20250201000041
+
+-- ============================================================================
+-- Cadences
+-- ============================================================================
+-- A sequence of follow-up activities for leads of a source and/or stage. A
+-- cadence without a source or stage applies to every source or stage; the
+-- most specific active cadence wins, stage before source.
+
+CREATE TABLE IF NOT EXISTS lead_cadences (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    source_id uuid REFERENCES lead_sources(id) ON DELETE CASCADE,
+    stage_id uuid REFERENCES lead_stages(id) ON DELETE CASCADE,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_cadences_unique
+    ON lead_cadences(organization_id, COALESCE(source_id, '00000000-0000-0000-0000-000000000000'::uuid),
+        COALESCE(stage_id, '00000000-0000-0000-0000-000000000000'::uuid));
+
+-- The activity templates of a cadence, each due offset_minutes after the
+-- lead entered the cadence's scope
+CREATE TABLE IF NOT EXISTS lead_cadence_steps (
+    cadence_id uuid NOT NULL REFERENCES lead_cadences(id) ON DELETE CASCADE,
+    sequence integer NOT NULL,
+    offset_minutes integer NOT NULL,
+    activity_type varchar(50) NOT NULL,
+    summary text NOT NULL,
+    note text,
+
+    PRIMARY KEY (cadence_id, sequence),
+    CONSTRAINT lead_cadence_steps_offset_check CHECK (offset_minutes >= 0),
+    CONSTRAINT lead_cadence_steps_type_check CHECK (activity_type IN ('call', 'meeting', 'email', 'todo'))
+);
+
+-- ============================================================================
+-- Enrollments
+-- ============================================================================
+-- One row per lead entering a cadence's scope: its current stage entry for
+-- a cadence with a stage, its creation otherwise. Entries older than the
+-- cadence are not enrolled. The scope is copied from the cadence, so an
+-- enrollment is stopped when the lead leaves it, closes or is deleted, even
+-- once the cadence changed; it completes when no task is pending.
+
+CREATE TABLE IF NOT EXISTS lead_cadence_enrollments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    cadence_id uuid REFERENCES lead_cadences(id) ON DELETE SET NULL,
+    cadence_name varchar(255) NOT NULL,
+    source_id uuid,
+    stage_id uuid,
+    assigned_to uuid,
+    entered_at timestamptz NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'active',
+    finished_at timestamptz,
+
+    CONSTRAINT lead_cadence_enrollments_status_check CHECK (status IN ('active', 'completed', 'stopped'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lead_cadence_enrollments_entry ON lead_cadence_enrollments(lead_id, cadence_id, entered_at);
+CREATE INDEX IF NOT EXISTS idx_lead_cadence_enrollments_active ON lead_cadence_enrollments(lead_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_lead_cadence_enrollments_cadence ON lead_cadence_enrollments(organization_id, cadence_id);
+
+-- One task per step of an enrollment, with the activity it created. A task
+-- is done when its activity is, and skipped when the activity is cancelled
+-- or deleted, the task is skipped by hand or its enrollment stops.
+
+CREATE TABLE IF NOT EXISTS lead_cadence_tasks (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    enrollment_id uuid NOT NULL REFERENCES lead_cadence_enrollments(id) ON DELETE CASCADE,
+    sequence integer NOT NULL,
+    activity_id uuid REFERENCES activities(id) ON DELETE SET NULL,
+    activity_type varchar(50) NOT NULL,
+    summary text NOT NULL,
+    due_at timestamptz NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    completed_at timestamptz,
+    skipped_by uuid,
+
+    CONSTRAINT lead_cadence_tasks_status_check CHECK (status IN ('pending', 'done', 'skipped')),
+    UNIQUE (enrollment_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_cadence_tasks_pending ON lead_cadence_tasks(enrollment_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_lead_cadence_tasks_activity ON lead_cadence_tasks(activity_id);
@@ -0,0 +1,98 @@
+-- Migration: Scheduling Links
+-- Description: Calendar events for free/busy, public scheduling links of users and sales teams, and the meetings prospects book through them
+-- Version: 20250201000042
+
+-- ============================================================================
+-- Calendar events
+-- ============================================================================
+-- A user's meetings and other busy time. Events that are not cancelled are
+-- busy when offering booking slots. res_model/res_id link an event to the
+-- record it is about, such as the lead that booked it.
+
+CREATE TABLE IF NOT EXISTS calendar_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    title varchar(255) NOT NULL,
+    description text,
+    location varchar(255),
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'confirmed',
+    res_model varchar(100),
+    res_id uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT calendar_events_status_check CHECK (status IN ('confirmed', 'cancelled')),
+    CONSTRAINT calendar_events_range_check CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_events_user_time
+    ON calendar_events(organization_id, user_id, starts_at, ends_at) WHERE status <> 'cancelled';
+
+-- ============================================================================
+-- Scheduling links
+-- ============================================================================
+-- A public booking page, reached with its token, for one user or for a
+-- sales team, whose members share the bookings. Slots follow the business
+-- hours of the team, or of the organization for a user's link, and skip the
+-- hosts' busy time.
+
+CREATE TABLE IF NOT EXISTS scheduling_links (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    token varchar(64) NOT NULL UNIQUE,
+    user_id uuid,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE CASCADE,
+    duration_minutes integer NOT NULL,
+    step_minutes integer NOT NULL,
+    buffer_minutes integer NOT NULL DEFAULT 0,
+    min_notice_minutes integer NOT NULL DEFAULT 0,
+    horizon_days integer NOT NULL DEFAULT 30,
+    source_id uuid REFERENCES lead_sources(id) ON DELETE SET NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT scheduling_links_host_check CHECK ((user_id IS NULL) <> (team_id IS NULL)),
+    CONSTRAINT scheduling_links_duration_check CHECK (duration_minutes > 0 AND step_minutes > 0),
+    CONSTRAINT scheduling_links_notice_check CHECK (buffer_minutes >= 0 AND min_notice_minutes >= 0),
+    CONSTRAINT scheduling_links_horizon_check CHECK (horizon_days > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduling_links_org ON scheduling_links(organization_id);
+
+-- ============================================================================
+-- Bookings
+-- ============================================================================
+-- A meeting booked through a link, with the calendar event on the host's
+-- calendar and the meeting activity on the lead. attribution records how
+-- the lead was found: the link's tracking parameter, the invitee's email,
+-- or a lead created for the booking.
+
+CREATE TABLE IF NOT EXISTS lead_bookings (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    link_id uuid REFERENCES scheduling_links(id) ON DELETE SET NULL,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    host_user_id uuid NOT NULL,
+    event_id uuid REFERENCES calendar_events(id) ON DELETE SET NULL,
+    activity_id uuid REFERENCES activities(id) ON DELETE SET NULL,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    invitee_name varchar(255) NOT NULL,
+    invitee_email varchar(255) NOT NULL,
+    notes text,
+    attribution varchar(20) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT lead_bookings_attribution_check CHECK (attribution IN ('tracking', 'email', 'created'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_bookings_lead ON lead_bookings(lead_id);
+CREATE INDEX IF NOT EXISTS idx_lead_bookings_link_host ON lead_bookings(link_id, host_user_id);
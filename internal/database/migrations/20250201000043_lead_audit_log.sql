@@ -0,0 +1,27 @@
+-- Migration: Lead Audit Log
+-- Description: Field-level change history of leads
+-- Version: 20250201000043
+
+-- ============================================================================
+-- Lead audit log
+-- ============================================================================
+-- One row per field changed by a lead update, with the value before and
+-- after as JSON. Rows of the same update share changed_at. Entries are kept
+-- when the lead is deleted so the organization's export stays complete.
+
+CREATE TABLE IF NOT EXISTS lead_audit_log (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    lead_id uuid NOT NULL,
+    field varchar(100) NOT NULL,
+    old_value jsonb NOT NULL DEFAULT 'null',
+    new_value jsonb NOT NULL DEFAULT 'null',
+    source varchar(20) NOT NULL,
+    changed_by uuid,
+    changed_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT lead_audit_log_source_check CHECK (source IN ('update', 'bulk_update'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_audit_log_lead ON lead_audit_log(lead_id, changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_lead_audit_log_org_time ON lead_audit_log(organization_id, changed_at);
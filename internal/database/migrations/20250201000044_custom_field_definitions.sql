@@ -0,0 +1,52 @@
+-- Migration: Custom Field Definitions
+-- Description: Typed schema for the custom_fields JSON column of entities
+-- Version: 20250201000044
+
+-- ============================================================================
+-- Custom Field Definitions
+-- ============================================================================
+-- Each row defines one key of an entity's custom_fields column. Values are
+-- checked against the definitions when records are created or updated;
+-- entities without definitions keep accepting any JSON object. options lists
+-- the allowed values of select and multi_select fields. position orders the
+-- fields on forms.
+
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity varchar(50) NOT NULL,
+    name varchar(50) NOT NULL,
+    label varchar(255) NOT NULL,
+    type varchar(20) NOT NULL,
+    required boolean NOT NULL DEFAULT false,
+    options text[] NOT NULL DEFAULT '{}',
+    position integer NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT custom_field_definitions_org_entity_name_unique UNIQUE (organization_id, entity, name),
+    CONSTRAINT custom_field_definitions_type_check CHECK (
+        type IN ('text', 'number', 'boolean', 'date', 'datetime', 'select', 'multi_select')
+    )
+);
+
+-- ============================================================================
+-- Filtering
+-- ============================================================================
+-- Custom field filters on lead lists compile to custom_fields @> '{...}'
+
+CREATE INDEX IF NOT EXISTS idx_leads_custom_fields
+    ON leads USING gin(custom_fields jsonb_path_ops)
+    WHERE deleted_at IS NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'custom_fields', 'read'),
+    ('p', 'role:admin', 'custom_fields', 'manage'),
+    ('p', 'role:sales', 'custom_fields', 'read'),
+    ('p', 'role:accountant', 'custom_fields', 'read'),
+    ('p', 'role:viewer', 'custom_fields', 'read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,141 @@
+-- Migration: Customer Portal
+-- Description: Portal accounts of customer contacts with magic-link and password sign-in, support tickets and return requests raised by customers
+-- Version: 20250201000045
+
+-- ============================================================================
+-- Portal accounts
+-- ============================================================================
+-- A customer's sign-in to the portal, tied to a contact. The account sees
+-- the quotes, orders, invoices and shipments of its contact and of the
+-- contact's child contacts. Accounts are invited by staff and become active
+-- on their first sign-in; password_hash is set once the customer chooses a
+-- password, until then they sign in with magic links.
+
+CREATE TABLE IF NOT EXISTS portal_accounts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    email varchar(255) NOT NULL,
+    password_hash text,
+    status varchar(20) NOT NULL DEFAULT 'invited',
+    last_login_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT portal_accounts_contact_unique UNIQUE (organization_id, contact_id),
+    CONSTRAINT portal_accounts_status_check CHECK (status IN ('invited', 'active', 'disabled'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS portal_accounts_email_uidx
+    ON portal_accounts(organization_id, lower(email));
+
+-- Single-use sign-in links. Only the SHA-256 of the token is kept.
+CREATE TABLE IF NOT EXISTS portal_magic_links (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    account_id uuid NOT NULL REFERENCES portal_accounts(id) ON DELETE CASCADE,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_portal_magic_links_account ON portal_magic_links(account_id);
+
+-- Signed-in portal sessions, presented as bearer tokens. Only the SHA-256
+-- of the token is kept.
+CREATE TABLE IF NOT EXISTS portal_sessions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    account_id uuid NOT NULL REFERENCES portal_accounts(id) ON DELETE CASCADE,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_portal_sessions_account ON portal_sessions(account_id) WHERE revoked_at IS NULL;
+
+-- ============================================================================
+-- Support tickets
+-- ============================================================================
+-- Questions and problems raised by customers. Open and pending tickets are
+-- the customer's open tickets.
+
+CREATE TABLE IF NOT EXISTS support_tickets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    portal_account_id uuid REFERENCES portal_accounts(id) ON DELETE SET NULL,
+    subject varchar(255) NOT NULL,
+    description text NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'open',
+    priority varchar(20) NOT NULL DEFAULT 'normal',
+    resolved_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT support_tickets_status_check CHECK (status IN ('open', 'pending', 'resolved', 'closed')),
+    CONSTRAINT support_tickets_priority_check CHECK (priority IN ('low', 'normal', 'high', 'urgent'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_tickets_org_status ON support_tickets(organization_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_support_tickets_contact ON support_tickets(contact_id, created_at DESC);
+
+-- ============================================================================
+-- Return requests
+-- ============================================================================
+-- Goods a customer asks to return from a confirmed order. Lines cannot
+-- exceed the delivered quantity less what other requests that were not
+-- rejected already return.
+
+CREATE TABLE IF NOT EXISTS return_requests (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    order_id uuid NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    portal_account_id uuid REFERENCES portal_accounts(id) ON DELETE SET NULL,
+    reason text NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'requested',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT return_requests_status_check CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_org_status ON return_requests(organization_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_return_requests_order ON return_requests(order_id);
+
+CREATE TABLE IF NOT EXISTS return_request_lines (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id uuid NOT NULL REFERENCES return_requests(id) ON DELETE CASCADE,
+    order_line_id uuid NOT NULL REFERENCES sales_order_lines(id) ON DELETE CASCADE,
+    quantity numeric(15,4) NOT NULL,
+
+    CONSTRAINT return_request_lines_unique UNIQUE (return_id, order_line_id),
+    CONSTRAINT return_request_lines_quantity_check CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_request_lines_order_line ON return_request_lines(order_line_id);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'portal', 'manage'),
+    ('p', 'role:admin', 'support_tickets', 'read'),
+    ('p', 'role:admin', 'support_tickets', 'manage'),
+    ('p', 'role:admin', 'return_requests', 'read'),
+    ('p', 'role:admin', 'return_requests', 'manage'),
+    ('p', 'role:sales', 'portal', 'manage'),
+    ('p', 'role:sales', 'support_tickets', 'read'),
+    ('p', 'role:sales', 'support_tickets', 'manage'),
+    ('p', 'role:sales', 'return_requests', 'read'),
+    ('p', 'role:sales', 'return_requests', 'manage'),
+    ('p', 'role:viewer', 'support_tickets', 'read'),
+    ('p', 'role:viewer', 'return_requests', 'read')
+ON CONFLICT DO NOTHING;
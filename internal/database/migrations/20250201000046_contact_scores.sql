@@ -0,0 +1,52 @@
+-- Migration: Contact Engagement Scores
+-- Description: Per-organization weights of the signals contact engagement scores are calculated from, and the daily score history behind score trend charts
+-- Version: 20250201000046
+
+-- ============================================================================
+-- Score Weights
+-- ============================================================================
+-- Relative weights of the engagement signals: how recently and how often the
+-- contact was engaged, how often it answers emails and how many deals it
+-- won. Only their proportions matter. Organizations without a row use the
+-- defaults of 30/30/20/20.
+
+CREATE TABLE IF NOT EXISTS contact_score_weights (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    recency_weight integer NOT NULL,
+    frequency_weight integer NOT NULL,
+    response_rate_weight integer NOT NULL,
+    won_deals_weight integer NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT contact_score_weights_range_check CHECK (
+        recency_weight BETWEEN 0 AND 100 AND frequency_weight BETWEEN 0 AND 100
+        AND response_rate_weight BETWEEN 0 AND 100 AND won_deals_weight BETWEEN 0 AND 100),
+    CONSTRAINT contact_score_weights_total_check CHECK (
+        recency_weight + frequency_weight + response_rate_weight + won_deals_weight > 0)
+);
+
+-- ============================================================================
+-- Score History
+-- ============================================================================
+-- One row per contact and day, written by the nightly recalculation worker.
+-- A rerun of the same day replaces its rows. response_score is null when no
+-- email was sent to the contact in the scoring window.
+
+CREATE TABLE IF NOT EXISTS contact_score_history (
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    scored_on date NOT NULL,
+    engagement_score integer NOT NULL,
+    lead_score integer NOT NULL,
+    recency_score integer NOT NULL,
+    frequency_score integer NOT NULL,
+    response_score integer,
+    won_deals_score integer NOT NULL,
+    last_activity_at timestamptz,
+    calculated_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (contact_id, scored_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_score_history_org_day ON contact_score_history(organization_id, scored_on);
@@ -0,0 +1,44 @@
+-- Migration: White-label Email Domains
+-- Description: Organizations' own sending domains, verified through DKIM and SPF records, with replies routed to CRM inbound mailboxes, and branded tracking-page domains verified through CNAME records
+-- Version: 20250201000047
+
+-- ============================================================================
+-- Email Domains
+-- ============================================================================
+-- A sending domain carries the DKIM key pair outgoing mail is signed with;
+-- the organization publishes the public key under the selector and adds the
+-- platform's SPF include to the domain's SPF record. reply_to_inbox_id
+-- points replies at a lead email inbox, so they are threaded back onto
+-- their leads. A tracking domain is pointed at the platform's tracking host
+-- with a CNAME record.
+--
+-- records holds the expected DNS records with the outcome of their last
+-- check. An organization may add any domain, but only one organization can
+-- verify it: verifying proves control of the DNS zone.
+
+CREATE TABLE IF NOT EXISTS email_domains (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    domain varchar(253) NOT NULL,
+    kind varchar(20) NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    dkim_selector varchar(63),
+    dkim_public_key text,
+    dkim_private_key text,
+    reply_to_inbox_id uuid REFERENCES lead_email_inboxes(id) ON DELETE SET NULL,
+    records jsonb NOT NULL DEFAULT '[]',
+    last_checked_at timestamptz,
+    verified_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT email_domains_org_domain_unique UNIQUE (organization_id, domain, kind),
+    CONSTRAINT email_domains_kind_check CHECK (kind IN ('sending', 'tracking')),
+    CONSTRAINT email_domains_status_check CHECK (status IN ('pending', 'verified', 'failed')),
+    CONSTRAINT email_domains_dkim_check CHECK (
+        kind <> 'sending' OR (dkim_selector IS NOT NULL AND dkim_public_key IS NOT NULL AND dkim_private_key IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS email_domains_verified_uidx ON email_domains(domain, kind) WHERE status = 'verified';
+CREATE INDEX IF NOT EXISTS idx_email_domains_check ON email_domains(status, last_checked_at);
@@ -0,0 +1,23 @@
+-- Migration: Document Batch Archives
+-- Description: Batches that select their records (e.g. all invoices of a month, all PODs of a route) and packaged batches, whose PDFs are zipped into a downloadable archive in object storage
+-- Version: 20250201000048
+
+-- ============================================================================
+-- Batch selections and archives
+-- ============================================================================
+-- selection records the filter a batch's items were resolved from, for
+-- display; items remain the list that is generated. Packaged batches keep
+-- the storage key, size and checksum of their archive until it expires,
+-- after which the archive is deleted and the columns cleared.
+
+ALTER TABLE document_batches
+    ADD COLUMN IF NOT EXISTS selection jsonb,
+    ADD COLUMN IF NOT EXISTS package boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS archive_key text,
+    ADD COLUMN IF NOT EXISTS archive_size bigint,
+    ADD COLUMN IF NOT EXISTS archive_sha256 char(64),
+    ADD COLUMN IF NOT EXISTS archive_expires_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_document_batches_archive_expiry
+    ON document_batches(archive_expires_at)
+    WHERE archive_key IS NOT NULL;
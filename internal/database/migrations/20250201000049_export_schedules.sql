@@ -0,0 +1,108 @@
+-- Migration: Scheduled Exports
+-- Description: Customers' SFTP servers and S3 buckets, schedules exporting entity extracts and reports to them as CSV, and the log of every transfer
+-- Version: 20250201000049
+
+-- ============================================================================
+-- Export Destinations
+-- ============================================================================
+-- sealed_credentials holds the password, private key or access keys,
+-- encrypted with the server's export credential key; they are never
+-- returned. SFTP destinations pin the server's host key.
+
+CREATE TABLE IF NOT EXISTS export_destinations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    kind varchar(20) NOT NULL,
+    host varchar(255),
+    port integer,
+    username varchar(255),
+    host_key text,
+    bucket varchar(255),
+    region varchar(50),
+    endpoint varchar(500),
+    path varchar(1000) NOT NULL DEFAULT '',
+    sealed_credentials bytea,
+    active boolean NOT NULL DEFAULT true,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT export_destinations_kind_check CHECK (kind IN ('sftp', 's3')),
+    CONSTRAINT export_destinations_sftp_check CHECK (
+        kind <> 'sftp' OR (host IS NOT NULL AND port IS NOT NULL AND username IS NOT NULL AND host_key IS NOT NULL)),
+    CONSTRAINT export_destinations_s3_check CHECK (kind <> 's3' OR bucket IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_destinations_org ON export_destinations(organization_id);
+
+-- ============================================================================
+-- Export Schedules
+-- ============================================================================
+-- A schedule runs at time_of_day in its timezone, every day, on weekday
+-- (0 is Sunday) or on day_of_month. next_run_at is moved before each run.
+-- Incremental schedules export the rows changed since last_success_at.
+
+CREATE TABLE IF NOT EXISTS export_schedules (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    destination_id uuid NOT NULL REFERENCES export_destinations(id) ON DELETE RESTRICT,
+    source varchar(100) NOT NULL,
+    filename varchar(255) NOT NULL,
+    incremental boolean NOT NULL DEFAULT false,
+    frequency varchar(20) NOT NULL,
+    time_of_day time NOT NULL,
+    weekday smallint,
+    day_of_month smallint,
+    timezone varchar(100) NOT NULL DEFAULT 'UTC',
+    alert_emails text[] NOT NULL DEFAULT '{}',
+    active boolean NOT NULL DEFAULT true,
+    next_run_at timestamptz,
+    last_run_at timestamptz,
+    last_status varchar(20),
+    last_success_at timestamptz,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT export_schedules_frequency_check CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    CONSTRAINT export_schedules_weekday_check CHECK (frequency <> 'weekly' OR weekday BETWEEN 0 AND 6),
+    CONSTRAINT export_schedules_day_check CHECK (frequency <> 'monthly' OR day_of_month BETWEEN 1 AND 28),
+    CONSTRAINT export_schedules_status_check CHECK (last_status IN ('succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_schedules_org ON export_schedules(organization_id);
+CREATE INDEX IF NOT EXISTS idx_export_schedules_due ON export_schedules(next_run_at) WHERE active;
+
+-- ============================================================================
+-- Export Transfers
+-- ============================================================================
+-- The log of every run. It outlives its schedule and destination, so it
+-- does not reference them.
+
+CREATE TABLE IF NOT EXISTS export_transfers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    schedule_id uuid NOT NULL,
+    destination_id uuid NOT NULL,
+    source varchar(100) NOT NULL,
+    trigger varchar(20) NOT NULL,
+    status varchar(20) NOT NULL,
+    remote_path varchar(1500) NOT NULL DEFAULT '',
+    rows integer NOT NULL DEFAULT 0,
+    size bigint NOT NULL DEFAULT 0,
+    sha256 varchar(64),
+    since timestamptz,
+    error text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    triggered_by uuid,
+
+    CONSTRAINT export_transfers_trigger_check CHECK (trigger IN ('scheduled', 'manual')),
+    CONSTRAINT export_transfers_status_check CHECK (status IN ('succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_transfers_org ON export_transfers(organization_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_export_transfers_schedule ON export_transfers(schedule_id, started_at DESC);
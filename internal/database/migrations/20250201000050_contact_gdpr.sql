@@ -0,0 +1,46 @@
+-- Migration: Contact GDPR Requests
+-- Description: The audit record of contact data exports and anonymizations, and the approval of each anonymization by a second user
+-- Version: 20250201000050
+
+-- ============================================================================
+-- Anonymized Contacts
+-- ============================================================================
+-- An anonymized contact keeps its row, so the invoices and orders referencing
+-- it still balance, but its personal data is replaced by a pseudonym.
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS anonymized_at timestamptz;
+
+-- ============================================================================
+-- GDPR Requests
+-- ============================================================================
+-- Exports are recorded as completed when they are downloaded, with the
+-- counts of each section and the SHA-256 of the archive. Anonymizations are
+-- requested with a reason, then approved or rejected by another user; the
+-- summary of a completed one counts the rows pseudonymized in each table.
+
+CREATE TABLE IF NOT EXISTS contact_gdpr_requests (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    kind varchar(20) NOT NULL,
+    status varchar(20) NOT NULL,
+    reason text,
+    requested_by uuid NOT NULL,
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    reviewed_by uuid,
+    reviewed_at timestamptz,
+    review_note text,
+    completed_at timestamptz,
+    summary jsonb NOT NULL DEFAULT '{}'::jsonb,
+    sha256 varchar(64),
+
+    CONSTRAINT contact_gdpr_requests_kind_check CHECK (kind IN ('export', 'anonymize')),
+    CONSTRAINT contact_gdpr_requests_status_check CHECK (status IN ('pending', 'completed', 'rejected')),
+    CONSTRAINT contact_gdpr_requests_review_check CHECK (status <> 'rejected' OR review_note IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_gdpr_requests_contact ON contact_gdpr_requests(contact_id, requested_at DESC);
+
+-- A contact has at most one anonymization awaiting approval
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_gdpr_requests_pending
+    ON contact_gdpr_requests(contact_id) WHERE kind = 'anonymize' AND status = 'pending';
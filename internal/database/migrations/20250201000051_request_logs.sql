@@ -0,0 +1,83 @@
+-- Migration: API Request Log
+-- Description: Sampled log of API requests with captured payloads for platform administrators, per-organization capture settings, and replays of failed requests in sandbox organizations
+-- Version: 20250201000051
+
+-- ============================================================================
+-- Capture Settings
+-- ============================================================================
+-- Every failed request is logged and sample_rate of the others. Organizations
+-- without a row use the defaults: 1% of successful requests, both bodies
+-- captured up to 16 KiB, and the common secret fields redacted.
+
+CREATE TABLE IF NOT EXISTS request_log_settings (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    enabled boolean NOT NULL DEFAULT true,
+    sample_rate numeric(5,4) NOT NULL,
+    capture_request_body boolean NOT NULL,
+    capture_response_body boolean NOT NULL,
+    max_body_bytes integer NOT NULL,
+    redact_fields text[] NOT NULL DEFAULT '{}',
+    updated_by uuid,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT request_log_settings_sample_rate_check CHECK (sample_rate BETWEEN 0 AND 1),
+    CONSTRAINT request_log_settings_max_body_check CHECK (max_body_bytes BETWEEN 0 AND 262144)
+);
+
+-- ============================================================================
+-- Request Log
+-- ============================================================================
+-- route is the path with IDs replaced by :id, so requests to the same
+-- endpoint can be searched together. Authorization, cookies and API keys are
+-- never stored; redacted body fields read [REDACTED]. Requests without an
+-- organization reached public routes. request_size is -1 when the body was
+-- streamed without a length. Rows are purged after the retention period.
+
+CREATE TABLE IF NOT EXISTS request_logs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid,
+    roles text[] NOT NULL DEFAULT '{}',
+    is_sandbox boolean NOT NULL DEFAULT false,
+    method varchar(10) NOT NULL,
+    route varchar(500) NOT NULL,
+    path varchar(2000) NOT NULL,
+    query text NOT NULL DEFAULT '',
+    status integer NOT NULL,
+    duration_ms integer NOT NULL,
+    client_ip varchar(64),
+    user_agent varchar(500),
+    request_headers jsonb NOT NULL DEFAULT '{}'::jsonb,
+    request_size bigint NOT NULL DEFAULT 0,
+    request_body text,
+    request_body_truncated boolean NOT NULL DEFAULT false,
+    response_body text,
+    response_body_truncated boolean NOT NULL DEFAULT false,
+    sampled boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_logs_org ON request_logs(organization_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_request_logs_route ON request_logs(route, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_request_logs_failed ON request_logs(created_at DESC) WHERE status >= 400;
+
+-- ============================================================================
+-- Replays
+-- ============================================================================
+-- A failed request sent again, as its original user, in the sandbox paired
+-- with its organization.
+
+CREATE TABLE IF NOT EXISTS request_log_replays (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    request_log_id uuid NOT NULL REFERENCES request_logs(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    replayed_by uuid NOT NULL,
+    status integer NOT NULL,
+    duration_ms integer NOT NULL,
+    response_headers jsonb NOT NULL DEFAULT '{}'::jsonb,
+    response_body text,
+    response_body_truncated boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_log_replays_log ON request_log_replays(request_log_id, created_at DESC);
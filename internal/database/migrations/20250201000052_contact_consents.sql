@@ -0,0 +1,73 @@
+-- Migration: Contact Communication Preferences
+-- Description: Per-channel opt-in and opt-out of contacts with double opt-in confirmation, unsubscribe tokens and consent audit history
+-- Version: 20250201000052
+
+-- ============================================================================
+-- Unsubscribe Tokens
+-- ============================================================================
+-- Every email can carry the contact's unsubscribe link. The token only lets
+-- its holder opt out, so it is stored as is and created on first use.
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS unsubscribe_token varchar(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_unsubscribe_token
+    ON contacts(unsubscribe_token) WHERE unsubscribe_token IS NOT NULL;
+
+-- ============================================================================
+-- Consents
+-- ============================================================================
+-- The current preference of a contact for a channel. Contacts without a row
+-- for a channel have not stated one. pending is an email opt-in waiting for
+-- the contact to confirm it from the link they were sent; only the hash of
+-- the confirmation token is stored.
+
+CREATE TABLE IF NOT EXISTS contact_consents (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    channel varchar(10) NOT NULL,
+    status varchar(20) NOT NULL,
+    source varchar(30) NOT NULL,
+    opted_in_at timestamptz,
+    confirmation_sent_at timestamptz,
+    confirmed_at timestamptz,
+    opted_out_at timestamptz,
+    confirmation_token_hash varchar(64),
+    updated_by uuid,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (contact_id, channel),
+    CONSTRAINT contact_consents_channel_check CHECK (channel IN ('email', 'sms', 'phone')),
+    CONSTRAINT contact_consents_status_check CHECK (status IN ('pending', 'opted_in', 'opted_out')),
+    CONSTRAINT contact_consents_pending_token_check CHECK (status <> 'pending' OR confirmation_token_hash IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_consents_org ON contact_consents(organization_id, channel, status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_consents_confirmation
+    ON contact_consents(confirmation_token_hash) WHERE confirmation_token_hash IS NOT NULL;
+
+-- ============================================================================
+-- Consent History
+-- ============================================================================
+-- Every change of a consent: who made it (a user, or the contact through a
+-- confirmation or unsubscribe link, with the address and browser they used)
+-- and the status before and after. previous_status is NULL when none had
+-- been stated.
+
+CREATE TABLE IF NOT EXISTS contact_consent_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contact_id uuid NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    channel varchar(10) NOT NULL,
+    previous_status varchar(20),
+    status varchar(20) NOT NULL,
+    source varchar(30) NOT NULL,
+    actor_id uuid,
+    ip_address varchar(64),
+    user_agent varchar(500),
+    note text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT contact_consent_events_source_check CHECK (source IN ('user', 'double_opt_in', 'unsubscribe_link'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_consent_events_contact ON contact_consent_events(contact_id, created_at DESC);
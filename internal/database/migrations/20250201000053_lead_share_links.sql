@@ -0,0 +1,34 @@
+-- Migration: Lead Share Links
+-- Description: Expiring read-only links to a filtered lead view for people without a login, with the fields they may see
+-- Version: 20250201000053
+
+-- ============================================================================
+-- Share Links
+-- ============================================================================
+-- A saved lead view (a query filter tree and a sort) shared through a public
+-- link until expires_at, or until it is revoked. fields lists the only lead
+-- fields the link shows; custom fields are named custom.<name>. The link
+-- token grants read access, so only its hash is stored. Views of the link
+-- are counted.
+
+CREATE TABLE IF NOT EXISTS lead_share_links (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    query jsonb,
+    sort_by varchar(50) NOT NULL DEFAULT '',
+    sort_dir varchar(4) NOT NULL DEFAULT '',
+    fields text[] NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    revoked_by uuid,
+    view_count integer NOT NULL DEFAULT 0,
+    last_viewed_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+
+    CONSTRAINT lead_share_links_fields_check CHECK (cardinality(fields) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_share_links_org ON lead_share_links(organization_id, created_at DESC);
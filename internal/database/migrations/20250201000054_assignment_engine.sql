@@ -0,0 +1,14 @@
+-- Migration: Assignment Engine
+-- Description: Drop the assignee selection functions, now chosen by the CRM assignment engine
+-- Version: 20250201000054
+
+-- ============================================================================
+-- Selection Functions
+-- ============================================================================
+-- Round robin, weighted and territory assignees are chosen in the
+-- application, which records the assignment, the assignee loads and the
+-- round robin position in one transaction.
+
+DROP FUNCTION IF EXISTS get_next_round_robin_user(uuid);
+DROP FUNCTION IF EXISTS get_weighted_user(uuid, varchar);
+DROP FUNCTION IF EXISTS match_territory(uuid, jsonb);
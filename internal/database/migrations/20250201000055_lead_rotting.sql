@@ -0,0 +1,61 @@
+-- Migration: Lead Rotting
+-- Description: Rotting days per lead stage, pool assignment rules returning leads to the unassigned pool, and the rotations of leads left untouched
+-- Version: 20250201000055
+
+-- ============================================================================
+-- Stage Rotting Days
+-- ============================================================================
+-- How many days an assigned lead may sit in the stage untouched before it is
+-- rotated through the assignment rules matching {"rotting": true}. Stages
+-- without rotting days never rot.
+
+ALTER TABLE lead_stages ADD COLUMN IF NOT EXISTS rotting_days integer;
+
+ALTER TABLE lead_stages DROP CONSTRAINT IF EXISTS lead_stages_rotting_days_check;
+ALTER TABLE lead_stages ADD CONSTRAINT lead_stages_rotting_days_check CHECK (rotting_days > 0);
+
+-- ============================================================================
+-- Pool Rules
+-- ============================================================================
+-- Pool rules assign no one: the record is unassigned and its history entry
+-- is assigned to the pool, without an assignee.
+
+ALTER TABLE assignment_rules DROP CONSTRAINT IF EXISTS assignment_rules_rule_type_check;
+ALTER TABLE assignment_rules ADD CONSTRAINT assignment_rules_rule_type_check
+    CHECK (rule_type IN ('round_robin', 'weighted', 'territory', 'custom', 'pool'));
+
+ALTER TABLE assignment_history ALTER COLUMN assigned_to_id DROP NOT NULL;
+ALTER TABLE assignment_history DROP CONSTRAINT IF EXISTS assignment_history_assigned_to_type_check;
+ALTER TABLE assignment_history ADD CONSTRAINT assignment_history_assigned_to_type_check
+    CHECK (assigned_to_type IN ('user', 'team', 'pool'));
+ALTER TABLE assignment_history DROP CONSTRAINT IF EXISTS assignment_history_assigned_to_check;
+ALTER TABLE assignment_history ADD CONSTRAINT assignment_history_assigned_to_check
+    CHECK ((assigned_to_type = 'pool') = (assigned_to_id IS NULL));
+
+-- ============================================================================
+-- Lead Rotations
+-- ============================================================================
+-- One row per rotting lead handled by the rotting worker. A lead is touched
+-- by an activity, a stage change, a (re)assignment or an edit by a user, and
+-- is not rotated again until it is touched and rots anew; failed rotations
+-- are retried.
+
+CREATE TABLE IF NOT EXISTS lead_rotations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    lead_id uuid NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+    stage_id uuid NOT NULL REFERENCES lead_stages(id) ON DELETE CASCADE,
+    team_id uuid REFERENCES sales_teams(id) ON DELETE SET NULL,
+    previous_assigned_to uuid NOT NULL,
+    assigned_to uuid,
+    result varchar(30) NOT NULL,
+    rotting_days integer NOT NULL,
+    last_touched_at timestamptz NOT NULL,
+    rotated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT lead_rotations_result_check
+        CHECK (result IN ('reassigned', 'returned_to_pool', 'kept', 'no_rule', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_rotations_lead ON lead_rotations(lead_id, rotated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_lead_rotations_org_time ON lead_rotations(organization_id, rotated_at);
@@ -0,0 +1,89 @@
+-- Migration: Data Quality
+-- Description: Per-organization data quality check settings, nightly evaluation runs and the work queue of offending records
+-- Version: 20250201000056
+
+-- ============================================================================
+-- Check Settings
+-- ============================================================================
+-- Rows only exist for organizations that override the defaults defined in the
+-- data quality module (every check enabled, leads unassigned for 1 day,
+-- shipments without news for 3 days).
+
+CREATE TABLE IF NOT EXISTS data_quality_settings (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    check_key varchar(50) NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    threshold_days integer,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+    CONSTRAINT data_quality_settings_org_check_unique UNIQUE (organization_id, check_key),
+    CONSTRAINT data_quality_settings_check_key_check CHECK (
+        check_key IN ('leads_without_owner', 'contacts_without_email', 'stalled_shipments', 'negative_stock')
+    ),
+    CONSTRAINT data_quality_settings_threshold_check CHECK (threshold_days > 0)
+);
+
+-- ============================================================================
+-- Evaluation Runs
+-- ============================================================================
+-- One row per check evaluated for an organization. The latest completed run
+-- of each check makes up the hygiene score of its module.
+
+CREATE TABLE IF NOT EXISTS data_quality_runs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    check_key varchar(50) NOT NULL,
+    module varchar(50) NOT NULL,
+    checked_count integer NOT NULL DEFAULT 0,
+    offending_count integer NOT NULL DEFAULT 0,
+    dismissed_count integer NOT NULL DEFAULT 0,
+    status varchar(20) NOT NULL,
+    error text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    CONSTRAINT data_quality_runs_status_check CHECK (status IN ('completed', 'skipped', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_quality_runs_org_check ON data_quality_runs(organization_id, check_key, started_at DESC);
+
+-- ============================================================================
+-- Issues
+-- ============================================================================
+-- One row per offending record and check. Issues are resolved once the record
+-- passes the check and reopened if it fails again; dismissed issues stay
+-- dismissed and leave the work queue.
+
+CREATE TABLE IF NOT EXISTS data_quality_issues (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    check_key varchar(50) NOT NULL,
+    module varchar(50) NOT NULL,
+    record_type varchar(50) NOT NULL,
+    record_id uuid NOT NULL,
+    record_label varchar(500) NOT NULL DEFAULT '',
+    details jsonb NOT NULL DEFAULT '{}'::jsonb,
+    status varchar(20) NOT NULL DEFAULT 'open',
+    first_seen_at timestamptz NOT NULL,
+    last_seen_at timestamptz NOT NULL,
+    resolved_at timestamptz,
+    dismissed_at timestamptz,
+    dismissed_by uuid,
+    CONSTRAINT data_quality_issues_record_unique UNIQUE (organization_id, check_key, record_id),
+    CONSTRAINT data_quality_issues_status_check CHECK (status IN ('open', 'resolved', 'dismissed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_quality_issues_queue
+    ON data_quality_issues(organization_id, module, first_seen_at)
+    WHERE status = 'open';
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'data_quality', 'read'),
+    ('p', 'role:admin', 'data_quality', 'manage'),
+    ('p', 'role:sales', 'data_quality', 'read')
+ON CONFLICT DO NOTHING;
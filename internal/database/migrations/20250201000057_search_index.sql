@@ -0,0 +1,111 @@
+-- Migration: Search Index
+-- Description: Search documents kept up to date from entity change events, the change outbox feeding them and per-organization reindex jobs
+-- Version: 20250201000057
+
+-- ============================================================================
+-- Index Outbox
+-- ============================================================================
+-- The search module records every entity change event it receives here. The
+-- indexer claims pending rows in id order, refreshes the documents of the
+-- changed records and marks the rows processed. Rows that fail are retried
+-- with a growing delay until they run out of attempts.
+
+CREATE TABLE IF NOT EXISTS search_index_events (
+    id bigserial PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    record_type varchar(50) NOT NULL,
+    record_id uuid NOT NULL,
+    event_type varchar(100) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    available_at timestamptz NOT NULL DEFAULT now(),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    processed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_index_events_pending
+    ON search_index_events(available_at, id)
+    WHERE processed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_search_index_events_org
+    ON search_index_events(organization_id, created_at)
+    WHERE processed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_search_index_events_processed
+    ON search_index_events(organization_id, processed_at DESC)
+    WHERE processed_at IS NOT NULL;
+
+-- ============================================================================
+-- Search Documents
+-- ============================================================================
+-- One row per indexed record with the fields quick search matches on.
+
+CREATE TABLE IF NOT EXISTS search_documents (
+    record_type varchar(50) NOT NULL,
+    record_id uuid NOT NULL,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    title text NOT NULL DEFAULT '',
+    subtitle text,
+    reference text,
+    source_updated_at timestamptz NOT NULL,
+    indexed_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (record_type, record_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_org_type ON search_documents(organization_id, record_type);
+CREATE INDEX IF NOT EXISTS idx_search_documents_title_trgm ON search_documents USING gin(title extensions.gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_search_documents_reference_trgm ON search_documents USING gin(reference extensions.gin_trgm_ops);
+
+-- Backfill from the records quick search used to read directly
+INSERT INTO search_documents (organization_id, record_type, record_id, title, subtitle, reference, source_updated_at)
+SELECT organization_id, 'contact', id, COALESCE(name, ''), city::text, email::text, updated_at
+FROM contacts WHERE deleted_at IS NULL
+UNION ALL
+SELECT organization_id, 'lead', id, COALESCE(name, ''), contact_name::text, email::text, updated_at
+FROM leads WHERE deleted_at IS NULL
+UNION ALL
+SELECT organization_id, 'product', id, COALESCE(name, ''), barcode::text, default_code::text, updated_at
+FROM products WHERE deleted_at IS NULL
+UNION ALL
+SELECT organization_id, 'sales_order', id, COALESCE(name, ''), client_order_ref::text, name::text, updated_at
+FROM sales_orders WHERE deleted_at IS NULL
+UNION ALL
+SELECT organization_id, 'invoice', id, COALESCE(name, ''), ref::text, name::text, updated_at
+FROM invoices WHERE deleted_at IS NULL
+ON CONFLICT (record_type, record_id) DO NOTHING;
+
+-- ============================================================================
+-- Reindex Jobs
+-- ============================================================================
+-- A reindex rebuilds the documents of an organization from the source tables.
+-- Only one job per organization can be queued or running at a time.
+
+CREATE TABLE IF NOT EXISTS search_reindex_jobs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    record_types text[] NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'queued',
+    indexed_count integer NOT NULL DEFAULT 0,
+    removed_count integer NOT NULL DEFAULT 0,
+    error text,
+    requested_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    finished_at timestamptz,
+    CONSTRAINT search_reindex_jobs_status_check CHECK (status IN ('queued', 'running', 'completed', 'failed'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS search_reindex_jobs_active_unique
+    ON search_reindex_jobs(organization_id)
+    WHERE status IN ('queued', 'running');
+
+CREATE INDEX IF NOT EXISTS idx_search_reindex_jobs_org ON search_reindex_jobs(organization_id, created_at DESC);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'search', 'manage')
+ON CONFLICT DO NOTHING;
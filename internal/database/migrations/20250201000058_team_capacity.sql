@@ -0,0 +1,26 @@
+-- Migration: Team Capacity
+-- Description: Scheduled unavailability of users, such as vacations, taken into account by automatic assignment
+-- Version: 20250201000058
+
+-- ============================================================================
+-- User Unavailability
+-- ============================================================================
+-- A user takes no automatic assignments of any target model while one of
+-- their periods is in progress. Capacity, weight and the availability switch
+-- stay on user_assignment_load.
+
+CREATE TABLE IF NOT EXISTS user_unavailability (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    reason varchar(255) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    CONSTRAINT user_unavailability_period_check CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_unavailability_user
+    ON user_unavailability(organization_id, user_id, ends_at);
+
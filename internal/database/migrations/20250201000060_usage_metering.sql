@@ -0,0 +1,68 @@
+-- Migration: Usage Metering
+-- Description: Per-organization daily usage counters and the billing events reported from them for metered billing
+-- Version: 20250201000060
+
+-- ============================================================================
+-- Daily Usage
+-- ============================================================================
+-- One row per organization, day (UTC) and metric. api_calls, emails_sent and
+-- shipments_tracked are added to through the day; stored_records and
+-- active_users are set when the day is closed.
+
+CREATE TABLE IF NOT EXISTS usage_daily (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    day date NOT NULL,
+    metric varchar(32) NOT NULL,
+    quantity bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (organization_id, day, metric),
+    CONSTRAINT usage_daily_metric_check CHECK (
+        metric IN ('api_calls', 'stored_records', 'active_users', 'shipments_tracked', 'emails_sent')
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_daily_day ON usage_daily(day);
+
+-- Users seen making API calls, counted into active_users when the day is
+-- closed
+CREATE TABLE IF NOT EXISTS usage_active_users (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    day date NOT NULL,
+    user_id uuid NOT NULL,
+    PRIMARY KEY (organization_id, day, user_id)
+);
+
+-- ============================================================================
+-- Billing Events
+-- ============================================================================
+-- One event per organization, closed day and metric, shaped for metered
+-- billing usage records: counters are reported as increments, gauges as the
+-- value to set. The idempotency key lets the billing bridge retry safely.
+
+CREATE TABLE IF NOT EXISTS usage_billing_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    metric varchar(32) NOT NULL,
+    day date NOT NULL,
+    quantity bigint NOT NULL,
+    action varchar(16) NOT NULL,
+    idempotency_key varchar(100) NOT NULL UNIQUE,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    external_id varchar(255),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    delivered_at timestamptz,
+    CONSTRAINT usage_billing_events_action_check CHECK (action IN ('increment', 'set')),
+    CONSTRAINT usage_billing_events_status_check CHECK (status IN ('pending', 'delivered'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_billing_events_pending
+    ON usage_billing_events(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_usage_billing_events_org ON usage_billing_events(organization_id, day DESC);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'usage', 'read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,39 @@
+-- Migration: Subscription Plans
+-- Description: Plan definitions with limits on users, leads, automations and API rate, and the features they include
+-- Version: 20250201000061
+
+-- ============================================================================
+-- Subscription Plans
+-- ============================================================================
+-- organizations.subscription_tier names the organization's plan; unknown
+-- tiers get the free plan. A NULL limit is unlimited. Sandbox organizations
+-- get the plan of the organization they are paired with.
+
+CREATE TABLE IF NOT EXISTS subscription_plans (
+    code varchar(50) PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    rank integer NOT NULL,
+    max_users integer,
+    max_leads integer,
+    max_automations integer,
+    api_rate_per_minute integer,
+    features text[] NOT NULL DEFAULT '{}',
+    upgrade_to varchar(50) REFERENCES subscription_plans(code),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT subscription_plans_limits_check CHECK (
+        COALESCE(max_users, 0) >= 0 AND COALESCE(max_leads, 0) >= 0 AND
+        COALESCE(max_automations, 0) >= 0 AND COALESCE(api_rate_per_minute, 1) > 0
+    )
+);
+
+INSERT INTO subscription_plans (code, name, rank, max_users, max_leads, max_automations, api_rate_per_minute, features) VALUES
+    ('free', 'Free', 0, 5, 500, 2, 120, '{}'),
+    ('starter', 'Starter', 1, 10, 10000, 10, 600, '{sandbox}'),
+    ('professional', 'Professional', 2, 50, 100000, 50, 1200, '{sandbox}'),
+    ('enterprise', 'Enterprise', 3, NULL, NULL, NULL, 6000, '{sandbox}')
+ON CONFLICT (code) DO NOTHING;
+
+UPDATE subscription_plans SET upgrade_to = 'starter' WHERE code = 'free' AND upgrade_to IS NULL;
+UPDATE subscription_plans SET upgrade_to = 'professional' WHERE code = 'starter' AND upgrade_to IS NULL;
+UPDATE subscription_plans SET upgrade_to = 'enterprise' WHERE code = 'professional' AND upgrade_to IS NULL;
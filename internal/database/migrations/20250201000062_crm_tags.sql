@@ -0,0 +1,38 @@
+-- Migration: CRM Tags
+-- Description: Organization tags shared by leads and contacts, with indexes for tag overlap filtering
+-- Version: 20250201000062
+
+-- ============================================================================
+-- CRM Tags
+-- ============================================================================
+-- leads.tag_ids and contacts.tags hold crm_tags ids. Existing contact tags
+-- are copied over with the same ids so tags already set on contacts keep
+-- resolving.
+
+CREATE TABLE IF NOT EXISTS crm_tags (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(100) NOT NULL,
+    color integer NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT crm_tags_name_check CHECK (btrim(name) <> '')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crm_tags_org_name ON crm_tags(organization_id, lower(name));
+
+INSERT INTO crm_tags (id, organization_id, name, color, created_at, updated_at)
+SELECT DISTINCT ON (organization_id, lower(name)) id, organization_id, name, COALESCE(color, 0), created_at, created_at
+FROM contact_tags
+WHERE btrim(name) <> ''
+ORDER BY organization_id, lower(name), created_at
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE crm_tags IS 'Tags applied to leads (leads.tag_ids) and contacts (contacts.tags)';
+
+-- ============================================================================
+-- Tag overlap filtering
+-- ============================================================================
+
+CREATE INDEX IF NOT EXISTS idx_leads_tag_ids ON leads USING gin (tag_ids);
+CREATE INDEX IF NOT EXISTS idx_contacts_tags ON contacts USING gin (tags);
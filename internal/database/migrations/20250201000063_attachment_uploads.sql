@@ -0,0 +1,15 @@
+-- Migration: Attachment Uploads
+-- Description: Let the same file be attached to several records of an organization
+-- Version: 20250201000063
+
+-- ============================================================================
+-- Attachments
+-- ============================================================================
+-- Each upload is its own attachment row and storage object, so a quote sent
+-- to two leads is attached to both. The checksum stays indexed for finding
+-- duplicates but no longer has to be unique in the organization.
+
+ALTER TABLE attachments DROP CONSTRAINT IF EXISTS attachments_checksum_unique;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_org_res ON attachments(organization_id, res_model, res_id, created_at DESC)
+    WHERE res_model IS NOT NULL;
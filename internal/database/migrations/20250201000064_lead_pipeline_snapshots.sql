@@ -0,0 +1,51 @@
+-- Migration: Lead Pipeline Snapshots
+-- Description: Weekly snapshots of the open pipeline per team and stage, and the stage each open lead was in, for week over week movement reports
+-- Version: 20250201000064
+
+-- ============================================================================
+-- Snapshot Totals
+-- ============================================================================
+-- One row per organization, week, team and stage with the open leads there
+-- when the week started (Monday 00:00 UTC). Leads without a team or stage
+-- are totalled under a NULL team or stage. Names are kept as they were so
+-- reports still read after a stage or team is renamed or deleted.
+
+CREATE TABLE IF NOT EXISTS lead_pipeline_snapshots (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    week_start date NOT NULL,
+    team_id uuid,
+    team_name varchar(255),
+    stage_id uuid,
+    stage_name varchar(100),
+    stage_sequence integer,
+    lead_count integer NOT NULL,
+    total_value numeric(17,2) NOT NULL,
+    weighted_value numeric(17,2) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lead_pipeline_snapshots_unique ON lead_pipeline_snapshots(
+    organization_id, week_start,
+    COALESCE(team_id, '00000000-0000-0000-0000-000000000000'),
+    COALESCE(stage_id, '00000000-0000-0000-0000-000000000000')
+);
+
+-- ============================================================================
+-- Snapshot Leads
+-- ============================================================================
+-- Where each open lead stood when the week started. Comparing two weeks
+-- tells the leads that entered, left, advanced or fell back in each stage.
+
+CREATE TABLE IF NOT EXISTS lead_pipeline_snapshot_leads (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    week_start date NOT NULL,
+    lead_id uuid NOT NULL,
+    team_id uuid,
+    stage_id uuid,
+    stage_sequence integer,
+    value numeric(15,2) NOT NULL,
+    probability integer NOT NULL,
+
+    PRIMARY KEY (organization_id, week_start, lead_id)
+);
@@ -0,0 +1,30 @@
+-- Migration: Lead Trash
+-- Description: Restoring soft-deleted leads and purging them after the organization's deleted leads retention
+-- Version: 20250201000065
+
+-- ============================================================================
+-- Deleted Leads Retention
+-- ============================================================================
+-- Soft-deleted leads stay in the trash for the deleted_leads retention (30
+-- days unless the organization overrides it) before they are hard-deleted.
+
+ALTER TABLE retention_policies DROP CONSTRAINT IF EXISTS retention_policies_entity_class_check;
+ALTER TABLE retention_policies ADD CONSTRAINT retention_policies_entity_class_check
+    CHECK (entity_class IN ('tracking_events', 'audit_logs', 'lost_leads', 'deleted_leads'));
+
+ALTER TABLE retention_legal_holds DROP CONSTRAINT IF EXISTS retention_legal_holds_entity_class_check;
+ALTER TABLE retention_legal_holds ADD CONSTRAINT retention_legal_holds_entity_class_check
+    CHECK (entity_class IN ('tracking_events', 'audit_logs', 'lost_leads', 'deleted_leads'));
+
+CREATE INDEX IF NOT EXISTS idx_leads_org_deleted ON leads(organization_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL;
+
+-- ============================================================================
+-- Restore Audit
+-- ============================================================================
+-- A restore is recorded as deleted_at going from when the lead was deleted
+-- back to null.
+
+ALTER TABLE lead_audit_log DROP CONSTRAINT IF EXISTS lead_audit_log_source_check;
+ALTER TABLE lead_audit_log ADD CONSTRAINT lead_audit_log_source_check
+    CHECK (source IN ('update', 'bulk_update', 'restore'));
@@ -0,0 +1,12 @@
+-- Migration: Lead Versions
+-- Description: Version counter on leads for optimistic locking of lead updates
+-- Version: 20250201000066
+
+-- ============================================================================
+-- Lead Versions
+-- ============================================================================
+-- Every write to a lead increments version. A lead update names the version
+-- it was made to and is refused when the lead has moved past it, instead of
+-- overwriting the writes it did not see.
+
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
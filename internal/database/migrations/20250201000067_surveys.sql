@@ -0,0 +1,119 @@
+-- Migration: Customer Surveys
+-- Description: NPS and CSAT survey definitions, invitations sent after deals are won, deliveries completed and tickets closed, tokenized responses and detractor follow-ups
+-- Version: 20250201000067
+
+-- ============================================================================
+-- Surveys
+-- ============================================================================
+-- A survey asks one scored question after a trigger event: an NPS question
+-- scored 0 to 10 or a CSAT question scored 1 to 5. Invitations go out by
+-- email or SMS delay_minutes after the event and can be answered for
+-- expiry_days. Detractor responses are emailed to detractor_alert_emails.
+
+CREATE TABLE IF NOT EXISTS surveys (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    kind varchar(10) NOT NULL,
+    trigger varchar(30) NOT NULL,
+    channel varchar(10) NOT NULL DEFAULT 'email',
+    question text NOT NULL,
+    message text NOT NULL DEFAULT '',
+    delay_minutes integer NOT NULL DEFAULT 0,
+    expiry_days integer NOT NULL DEFAULT 14,
+    detractor_alert_emails text[] NOT NULL DEFAULT '{}',
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT surveys_kind_check CHECK (kind IN ('nps', 'csat')),
+    CONSTRAINT surveys_trigger_check CHECK (trigger IN ('deal_won', 'delivery_completed', 'ticket_closed')),
+    CONSTRAINT surveys_channel_check CHECK (channel IN ('email', 'sms')),
+    CONSTRAINT surveys_delay_check CHECK (delay_minutes >= 0),
+    CONSTRAINT surveys_expiry_check CHECK (expiry_days > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_surveys_org_trigger ON surveys(organization_id, trigger) WHERE active;
+
+-- ============================================================================
+-- Invitations
+-- ============================================================================
+-- One invitation per survey and source record (the won lead, the delivered
+-- shipment or the closed ticket), so an event raised twice surveys once.
+-- The token is generated when the invitation is sent and only its SHA-256
+-- hash is kept. Failed sends are retried until attempts run out.
+
+CREATE TABLE IF NOT EXISTS survey_invitations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    survey_id uuid NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    trigger varchar(30) NOT NULL,
+    source_id uuid NOT NULL,
+    contact_id uuid REFERENCES contacts(id) ON DELETE SET NULL,
+    recipient_name varchar(255) NOT NULL DEFAULT '',
+    email varchar(255) NOT NULL DEFAULT '',
+    phone varchar(50) NOT NULL DEFAULT '',
+    channel varchar(10) NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    token_hash varchar(64),
+    send_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    sent_at timestamptz,
+    responded_at timestamptz,
+    attempts integer NOT NULL DEFAULT 0,
+    error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT survey_invitations_status_check CHECK (status IN ('pending', 'sent', 'failed', 'skipped', 'responded')),
+    CONSTRAINT survey_invitations_channel_check CHECK (channel IN ('email', 'sms'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_survey_invitations_source ON survey_invitations(survey_id, source_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_survey_invitations_token ON survey_invitations(token_hash) WHERE token_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_survey_invitations_due ON survey_invitations(send_at) WHERE status IN ('pending', 'failed');
+CREATE INDEX IF NOT EXISTS idx_survey_invitations_org ON survey_invitations(organization_id, created_at DESC);
+
+-- ============================================================================
+-- Responses
+-- ============================================================================
+-- One response per invitation. The category is derived from the score when
+-- the response is recorded. Detractor responses open a follow-up that stays
+-- open until someone resolves it.
+
+CREATE TABLE IF NOT EXISTS survey_responses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    survey_id uuid NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    invitation_id uuid NOT NULL UNIQUE REFERENCES survey_invitations(id) ON DELETE CASCADE,
+    contact_id uuid REFERENCES contacts(id) ON DELETE SET NULL,
+    score integer NOT NULL,
+    category varchar(10) NOT NULL,
+    comment text NOT NULL DEFAULT '',
+    responded_at timestamptz NOT NULL DEFAULT now(),
+    follow_up_status varchar(10),
+    follow_up_note text,
+    follow_up_resolved_at timestamptz,
+    follow_up_resolved_by uuid,
+
+    CONSTRAINT survey_responses_score_check CHECK (score BETWEEN 0 AND 10),
+    CONSTRAINT survey_responses_category_check CHECK (category IN ('promoter', 'passive', 'detractor')),
+    CONSTRAINT survey_responses_follow_up_check CHECK (follow_up_status IS NULL OR follow_up_status IN ('open', 'resolved'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_survey_responses_org_time ON survey_responses(organization_id, responded_at);
+CREATE INDEX IF NOT EXISTS idx_survey_responses_follow_up ON survey_responses(organization_id, follow_up_status, responded_at DESC)
+    WHERE follow_up_status IS NOT NULL;
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'surveys', 'read'),
+    ('p', 'role:admin', 'surveys', 'manage'),
+    ('p', 'role:sales', 'surveys', 'read'),
+    ('p', 'role:sales', 'surveys', 'manage'),
+    ('p', 'role:viewer', 'surveys', 'read')
+ON CONFLICT DO NOTHING;
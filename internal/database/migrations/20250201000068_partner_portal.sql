@@ -0,0 +1,102 @@
+-- Migration: Partner Portal
+-- Description: Partner role, partner organizations and their users, partner attribution on leads and deal registrations with conflict detection and approval
+-- Version: 20250201000068
+
+-- ============================================================================
+-- Partner Role
+-- ============================================================================
+-- Partner users sign in like any member of the organization but with the
+-- partner role, which only reaches the partner API.
+
+ALTER TABLE organization_users DROP CONSTRAINT IF EXISTS organization_users_role_check;
+ALTER TABLE organization_users ADD CONSTRAINT organization_users_role_check
+    CHECK (role IN ('owner', 'admin', 'manager', 'user', 'viewer', 'partner'));
+
+-- ============================================================================
+-- Partners
+-- ============================================================================
+-- A partner is a reseller or referral company. Its users are members of the
+-- organization with the partner role; a user belongs to at most one partner.
+
+CREATE TABLE IF NOT EXISTS partners (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    email varchar(255) NOT NULL DEFAULT '',
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_partners_org_name ON partners(organization_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS partner_users (
+    partner_id uuid NOT NULL REFERENCES partners(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (partner_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_partner_users_user ON partner_users(organization_id, user_id);
+
+-- ============================================================================
+-- Partner Attribution
+-- ============================================================================
+-- Leads created from approved deal registrations are attributed to the
+-- partner, whose pipeline is the leads attributed to it.
+
+ALTER TABLE leads ADD COLUMN IF NOT EXISTS partner_id uuid REFERENCES partners(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_leads_partner ON leads(partner_id) WHERE partner_id IS NOT NULL AND deleted_at IS NULL;
+
+-- ============================================================================
+-- Deal Registrations
+-- ============================================================================
+-- A partner registers a deal it is working on. The open leads it conflicts
+-- with, matched on email, email domain or company name, are recorded when
+-- it is registered and again when it is decided. Approving creates the
+-- partner's lead; a registration with conflicts is only approved by
+-- overriding them.
+
+CREATE TABLE IF NOT EXISTS partner_deal_registrations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    partner_id uuid NOT NULL REFERENCES partners(id) ON DELETE CASCADE,
+    registered_by uuid NOT NULL,
+    company_name varchar(255) NOT NULL,
+    contact_name varchar(255) NOT NULL DEFAULT '',
+    email varchar(255) NOT NULL DEFAULT '',
+    phone varchar(50) NOT NULL DEFAULT '',
+    expected_revenue numeric(15,2),
+    description text NOT NULL DEFAULT '',
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    conflict_lead_ids uuid[] NOT NULL DEFAULT '{}',
+    lead_id uuid REFERENCES leads(id) ON DELETE SET NULL,
+    decision_note text NOT NULL DEFAULT '',
+    decided_by uuid,
+    decided_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT partner_deal_registrations_status_check CHECK (status IN ('pending', 'approved', 'rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_partner_deal_registrations_partner ON partner_deal_registrations(partner_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_partner_deal_registrations_org_status ON partner_deal_registrations(organization_id, status, created_at DESC);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'partners', 'read'),
+    ('p', 'role:admin', 'partners', 'manage'),
+    ('p', 'role:sales', 'partners', 'read'),
+    ('p', 'role:sales', 'partners', 'manage'),
+    ('p', 'role:viewer', 'partners', 'read'),
+    ('p', 'role:partner', 'partner_portal', 'read'),
+    ('p', 'role:partner', 'partner_portal', 'register')
+ON CONFLICT DO NOTHING;
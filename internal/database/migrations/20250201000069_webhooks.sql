@@ -0,0 +1,71 @@
+-- Migration: Webhooks
+-- Description: Per-organization webhook subscriptions to lead events, signed deliveries retried with backoff and their delivery log
+-- Version: 20250201000069
+
+-- ============================================================================
+-- Subscriptions
+-- ============================================================================
+-- A subscription posts the events it names to an HTTPS endpoint. Every
+-- delivery is signed with the subscription's secret, which is only shown
+-- when the subscription is created and when the secret is rotated.
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    url varchar(2048) NOT NULL,
+    secret varchar(100) NOT NULL,
+    event_types text[] NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT webhook_subscriptions_event_types_check CHECK (cardinality(event_types) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_org ON webhook_subscriptions(organization_id) WHERE active;
+
+-- ============================================================================
+-- Deliveries
+-- ============================================================================
+-- One delivery per event and subscription. Deliveries of the same event
+-- share its event_id, which receivers can deduplicate on. A delivery that
+-- is not answered with a 2xx is retried with exponential backoff until its
+-- attempts run out, and then marked failed. Deliveries are kept 30 days.
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    subscription_id uuid NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id uuid NOT NULL,
+    event_type varchar(100) NOT NULL,
+    payload jsonb NOT NULL,
+    sandbox boolean NOT NULL DEFAULT false,
+    occurred_at timestamptz NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_attempt_at timestamptz,
+    response_status integer,
+    response_body text,
+    error text,
+    delivered_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created ON webhook_deliveries(created_at);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'webhooks', 'read'),
+    ('p', 'role:admin', 'webhooks', 'manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,120 @@
+-- Migration: Contracts and Renewals
+-- Description: Customer contracts with terms, auto-renewal and linked documents, renewal opportunities generated ahead of expiry and expiry reminder sequences
+-- Version: 20250201000070
+
+-- ============================================================================
+-- Contract Settings
+-- ============================================================================
+-- How far ahead of expiry renewal opportunities are created, unless a
+-- contract sets its own lead time, and the days before expiry reminders are
+-- emailed. Organizations without a row use the defaults.
+
+CREATE TABLE IF NOT EXISTS contract_settings (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    renewal_lead_days integer NOT NULL DEFAULT 60,
+    reminder_days integer[] NOT NULL DEFAULT '{90,30,7}',
+    notify_customer boolean NOT NULL DEFAULT false,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT contract_settings_renewal_lead_days_check CHECK (renewal_lead_days BETWEEN 0 AND 365)
+);
+
+-- ============================================================================
+-- Contracts
+-- ============================================================================
+-- A contract covers one term. Renewing it, by hand, by winning its renewal
+-- opportunity or automatically at the end of an auto-renewing term, creates
+-- the next term as a new contract pointing back at it.
+
+CREATE TABLE IF NOT EXISTS contracts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    reference varchar(100) NOT NULL DEFAULT '',
+    contact_id uuid REFERENCES contacts(id) ON DELETE SET NULL,
+    owner_id uuid,
+    status varchar(20) NOT NULL DEFAULT 'active',
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    value numeric(15,2) NOT NULL DEFAULT 0,
+    auto_renew boolean NOT NULL DEFAULT false,
+    renewal_term_months integer NOT NULL DEFAULT 12,
+    renewal_lead_days integer,
+    notes text NOT NULL DEFAULT '',
+    renewal_lead_id uuid REFERENCES leads(id) ON DELETE SET NULL,
+    renewed_from_id uuid REFERENCES contracts(id) ON DELETE SET NULL,
+    closed_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    created_by uuid,
+    updated_by uuid,
+
+    CONSTRAINT contracts_status_check CHECK (status IN ('draft', 'active', 'renewed', 'expired', 'cancelled')),
+    CONSTRAINT contracts_dates_check CHECK (end_date >= start_date),
+    CONSTRAINT contracts_value_check CHECK (value >= 0),
+    CONSTRAINT contracts_renewal_term_check CHECK (renewal_term_months BETWEEN 1 AND 120),
+    CONSTRAINT contracts_renewal_lead_days_check CHECK (renewal_lead_days IS NULL OR renewal_lead_days BETWEEN 0 AND 365)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contracts_org_status_end ON contracts(organization_id, status, end_date);
+CREATE INDEX IF NOT EXISTS idx_contracts_org_contact ON contracts(organization_id, contact_id) WHERE contact_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_contracts_active_end ON contracts(end_date) WHERE status = 'active';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contracts_renewal_lead ON contracts(renewal_lead_id) WHERE renewal_lead_id IS NOT NULL;
+-- A term is renewed at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contracts_renewed_from ON contracts(renewed_from_id) WHERE renewed_from_id IS NOT NULL;
+
+-- ============================================================================
+-- Contract Documents
+-- ============================================================================
+-- The signed contract, amendments and anything else filed with it: an
+-- uploaded attachment or a document generated from a template.
+
+CREATE TABLE IF NOT EXISTS contract_documents (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    contract_id uuid NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+    attachment_id uuid REFERENCES attachments(id) ON DELETE CASCADE,
+    generated_document_id uuid REFERENCES generated_documents(id) ON DELETE CASCADE,
+    label varchar(255) NOT NULL DEFAULT '',
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT contract_documents_source_check CHECK (num_nonnulls(attachment_id, generated_document_id) = 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contract_documents_attachment
+    ON contract_documents(contract_id, attachment_id) WHERE attachment_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contract_documents_generated
+    ON contract_documents(contract_id, generated_document_id) WHERE generated_document_id IS NOT NULL;
+
+-- ============================================================================
+-- Expiry Reminders
+-- ============================================================================
+-- One row per reminder step of a term, so each is emailed once. Steps that
+-- were already past when the contract was created are recorded as skipped.
+
+CREATE TABLE IF NOT EXISTS contract_reminders (
+    contract_id uuid NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    days_before integer NOT NULL,
+    status varchar(20) NOT NULL,
+    recipients text[] NOT NULL DEFAULT '{}',
+    error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (contract_id, days_before),
+    CONSTRAINT contract_reminders_status_check CHECK (status IN ('sent', 'skipped', 'failed'))
+);
+
+-- ============================================================================
+-- Permissions
+-- ============================================================================
+
+INSERT INTO casbin_rules (ptype, v0, v1, v2) VALUES
+    ('p', 'role:admin', 'contracts', 'read'),
+    ('p', 'role:admin', 'contracts', 'manage'),
+    ('p', 'role:sales', 'contracts', 'read'),
+    ('p', 'role:sales', 'contracts', 'manage'),
+    ('p', 'role:viewer', 'contracts', 'read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,141 @@
+-- Migration: Quote Margins
+-- Description: Line costs and margins on quotes, organization minimum margin thresholds and approval of low-margin quotes before they are confirmed
+-- Version: 20250201000071
+
+-- ============================================================================
+-- Line costs
+-- ============================================================================
+-- Open quotes are costed at the product's current standard price. The cost
+-- is frozen into cost_price when the order is confirmed, so margin analytics
+-- on confirmed orders do not move when product costs change later.
+
+ALTER TABLE sales_order_lines
+    ADD COLUMN IF NOT EXISTS cost_price numeric(15,2);
+
+-- ============================================================================
+-- Margin settings
+-- ============================================================================
+-- A quote needs approval before it is confirmed when its margin is below
+-- min_margin_percent, or when any product line's margin is below
+-- min_line_margin_percent. A NULL threshold is not enforced; organizations
+-- without settings never need approval.
+
+CREATE TABLE IF NOT EXISTS sales_margin_settings (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    min_margin_percent numeric(5,2),
+    min_line_margin_percent numeric(5,2),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT sales_margin_settings_margin_check CHECK (min_margin_percent IS NULL OR min_margin_percent BETWEEN -100 AND 100),
+    CONSTRAINT sales_margin_settings_line_margin_check CHECK (min_line_margin_percent IS NULL OR min_line_margin_percent BETWEEN -100 AND 100)
+);
+
+-- ============================================================================
+-- Margin approvals
+-- ============================================================================
+-- An approval covers the margins the quote had when approval was requested:
+-- a quote edited down to a lower margin afterwards needs a new approval.
+-- Requesters cannot review their own approvals.
+
+CREATE TABLE IF NOT EXISTS sales_margin_approvals (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    order_id uuid NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    amount_untaxed numeric(15,2) NOT NULL,
+    cost_total numeric(15,2) NOT NULL,
+    margin_percent numeric(7,2),
+    lowest_line_margin_percent numeric(7,2),
+    min_margin_percent numeric(5,2),
+    min_line_margin_percent numeric(5,2),
+    request_note text,
+    requested_by uuid NOT NULL,
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    reviewed_by uuid,
+    reviewed_at timestamptz,
+    review_note text,
+
+    CONSTRAINT sales_margin_approvals_status_check CHECK (status IN ('pending', 'approved', 'rejected')),
+    CONSTRAINT sales_margin_approvals_review_check CHECK (status = 'pending' OR reviewed_at IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_margin_approvals_pending
+    ON sales_margin_approvals(order_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_sales_margin_approvals_org
+    ON sales_margin_approvals(organization_id, status, requested_at DESC);
+
+-- ============================================================================
+-- Margin check
+-- ============================================================================
+-- sales_order_margin totals an order's untaxed amount and cost. Reward lines
+-- of promotions lower the amount without adding cost; lowest_line_margin
+-- only looks at product lines with a price.
+
+CREATE OR REPLACE FUNCTION sales_order_margin(p_order_id uuid)
+RETURNS TABLE (amount_untaxed numeric, cost_total numeric, margin_percent numeric, lowest_line_margin_percent numeric)
+LANGUAGE sql STABLE
+AS $$
+    WITH lines AS (
+        SELECT COALESCE(l.product_uom_qty, 0) * COALESCE(l.price_unit, 0) * (1 - COALESCE(l.discount, 0) / 100) AS amount,
+            CASE WHEN l.product_id IS NULL THEN 0
+                ELSE COALESCE(l.product_uom_qty, 0) * COALESCE(l.cost_price, p.standard_price, 0) END AS cost,
+            l.product_id
+        FROM sales_order_lines l
+        LEFT JOIN products p ON p.id = l.product_id
+        WHERE l.order_id = p_order_id AND l.deleted_at IS NULL AND l.display_type IS NULL
+    )
+    SELECT ROUND(SUM(amount), 2),
+        ROUND(SUM(cost), 2),
+        CASE WHEN SUM(amount) > 0 THEN ROUND((SUM(amount) - SUM(cost)) / SUM(amount) * 100, 2) END,
+        ROUND(MIN((amount - cost) / amount * 100) FILTER (WHERE product_id IS NOT NULL AND amount > 0), 2)
+    FROM lines
+$$;
+
+-- sales_orders_margin_approval stops quotes below the organization's margin
+-- thresholds from being confirmed without an approval covering their current
+-- margins, whichever path confirms them, and freezes line costs on
+-- confirmation.
+CREATE OR REPLACE FUNCTION sales_orders_margin_approval()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_settings sales_margin_settings%ROWTYPE;
+    v_margin record;
+BEGIN
+    SELECT * INTO v_settings FROM sales_margin_settings WHERE organization_id = NEW.organization_id;
+
+    IF FOUND THEN
+        SELECT * INTO v_margin FROM sales_order_margin(NEW.id);
+
+        IF (v_settings.min_margin_percent IS NOT NULL AND COALESCE(v_margin.margin_percent, 0) < v_settings.min_margin_percent)
+            OR (v_settings.min_line_margin_percent IS NOT NULL AND v_margin.lowest_line_margin_percent < v_settings.min_line_margin_percent)
+        THEN
+            IF NOT EXISTS (
+                SELECT 1 FROM sales_margin_approvals a
+                WHERE a.order_id = NEW.id AND a.status = 'approved'
+                    AND COALESCE(v_margin.margin_percent, 0) >= COALESCE(a.margin_percent, 0)
+                    AND (v_margin.lowest_line_margin_percent IS NULL OR a.lowest_line_margin_percent IS NULL
+                        OR v_margin.lowest_line_margin_percent >= a.lowest_line_margin_percent)
+            ) THEN
+                RAISE EXCEPTION 'Sales order % is below the minimum margin and needs an approved margin approval', NEW.id
+                    USING ERRCODE = 'check_violation';
+            END IF;
+        END IF;
+    END IF;
+
+    UPDATE sales_order_lines l SET cost_price = COALESCE(l.cost_price, p.standard_price, 0)
+    FROM products p
+    WHERE l.order_id = NEW.id AND p.id = l.product_id AND l.cost_price IS NULL;
+
+    RETURN NEW;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS sales_orders_margin_approval ON sales_orders;
+CREATE TRIGGER sales_orders_margin_approval
+    BEFORE UPDATE OF state ON sales_orders
+    FOR EACH ROW
+    WHEN (NEW.state = 'sale' AND OLD.state IN ('draft', 'sent'))
+    EXECUTE FUNCTION sales_orders_margin_approval();
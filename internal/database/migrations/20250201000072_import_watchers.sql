@@ -0,0 +1,107 @@
+-- Migration: Import Watchers
+-- Description: Saved CSV mappings, watchers polling export destinations for files to import with them, and the log of every imported file with its row errors
+-- Version: 20250201000072
+
+-- ============================================================================
+-- Import Mappings
+-- ============================================================================
+-- columns maps CSV headers to the fields of entity. Rows are matched to
+-- existing records on match_field; without it every row creates a record.
+
+CREATE TABLE IF NOT EXISTS import_mappings (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    entity varchar(100) NOT NULL,
+    columns jsonb NOT NULL DEFAULT '{}'::jsonb,
+    match_field varchar(100),
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_mappings_org ON import_mappings(organization_id);
+
+-- ============================================================================
+-- Import Watchers
+-- ============================================================================
+-- A watcher polls its destination's path every interval_minutes for files
+-- matching pattern, and moves the files it processed to archive_path,
+-- relative to the destination's path. next_poll_at is moved before each
+-- poll.
+
+CREATE TABLE IF NOT EXISTS import_watchers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name varchar(255) NOT NULL,
+    destination_id uuid NOT NULL REFERENCES export_destinations(id) ON DELETE RESTRICT,
+    mapping_id uuid NOT NULL REFERENCES import_mappings(id) ON DELETE RESTRICT,
+    pattern varchar(255) NOT NULL DEFAULT '*.csv',
+    archive_path varchar(1000) NOT NULL DEFAULT 'archive',
+    error_emails text[] NOT NULL DEFAULT '{}',
+    interval_minutes integer NOT NULL DEFAULT 15,
+    active boolean NOT NULL DEFAULT true,
+    next_poll_at timestamptz,
+    last_poll_at timestamptz,
+    last_status varchar(20),
+    last_error text,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    created_by uuid,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT import_watchers_interval_check CHECK (interval_minutes BETWEEN 5 AND 1440),
+    CONSTRAINT import_watchers_status_check CHECK (last_status IN ('succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_watchers_org ON import_watchers(organization_id);
+CREATE INDEX IF NOT EXISTS idx_import_watchers_due ON import_watchers(next_poll_at) WHERE active;
+
+-- ============================================================================
+-- Import Files
+-- ============================================================================
+-- The log of every file a watcher processed. It outlives its watcher and
+-- mapping, so it does not reference them. sha256 keeps a file that could
+-- not be archived from being imported again; it is null for files too
+-- large to download.
+
+CREATE TABLE IF NOT EXISTS import_files (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    watcher_id uuid NOT NULL,
+    mapping_id uuid NOT NULL,
+    entity varchar(100) NOT NULL,
+    remote_path varchar(1500) NOT NULL,
+    archived_path varchar(1500),
+    size bigint NOT NULL DEFAULT 0,
+    sha256 varchar(64),
+    status varchar(20) NOT NULL,
+    rows integer NOT NULL DEFAULT 0,
+    created integer NOT NULL DEFAULT 0,
+    updated integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    error text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+
+    CONSTRAINT import_files_status_check CHECK (status IN ('succeeded', 'partial', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_files_org ON import_files(organization_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_import_files_watcher ON import_files(watcher_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_import_files_hash ON import_files(watcher_id, sha256);
+
+-- ============================================================================
+-- Import Row Errors
+-- ============================================================================
+-- The first row errors of each file; line 1 is the header.
+
+CREATE TABLE IF NOT EXISTS import_row_errors (
+    id bigserial PRIMARY KEY,
+    file_id uuid NOT NULL REFERENCES import_files(id) ON DELETE CASCADE,
+    line integer NOT NULL,
+    field varchar(100),
+    message text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_row_errors_file ON import_row_errors(file_id, line);
@@ -0,0 +1,38 @@
+-- Migration: Security Event Forwarding
+-- Description: Administrative security events (members added and removed, role changes, API keys created, exports taken) in the security event log, and per-organization syslog sinks forwarding security events to a SIEM
+-- Version: 20250201000073
+
+-- ============================================================================
+-- Security Events
+-- ============================================================================
+-- Administrative changes join sign-ins and impersonation in the log. Every
+-- security event is also published, so it reaches webhook subscriptions
+-- naming its security.* type and the organization's syslog sink.
+
+ALTER TABLE security_events DROP CONSTRAINT IF EXISTS security_events_type_check;
+ALTER TABLE security_events ADD CONSTRAINT security_events_type_check CHECK (event_type IN (
+    'login.succeeded', 'login.failed', 'impersonation.started', 'impersonation.ended',
+    'user.added', 'user.removed', 'role.changed', 'api_key.created', 'export.performed'
+));
+
+-- ============================================================================
+-- Syslog Sinks
+-- ============================================================================
+-- At most one per organization. Security events are sent to it as RFC 5424
+-- messages carrying the webhook envelope as JSON, over UDP, TCP or TLS.
+-- Sends are not retried; the outcome of the last one is kept so that a
+-- misconfigured sink shows up.
+
+CREATE TABLE IF NOT EXISTS webhook_syslog_sinks (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    address varchar(255) NOT NULL,
+    protocol varchar(10) NOT NULL DEFAULT 'tls',
+    active boolean NOT NULL DEFAULT true,
+    last_sent_at timestamptz,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT webhook_syslog_sinks_protocol_check CHECK (protocol IN ('udp', 'tcp', 'tls'))
+);
@@ -0,0 +1,82 @@
+-- Migration: CRM Mobile Sync
+-- Description: Delta sync of leads, contacts and activities for the field sales app: tombstones of hard-deleted records, indexes for reading changes in order, and the operations the app pushed
+-- Version: 20250201000074
+
+-- ============================================================================
+-- Change Indexes
+-- ============================================================================
+-- The app reads an entity's changes ordered by (updated_at, id) past its
+-- cursor. Soft-deleted leads and contacts stay in these reads, flagged as
+-- deleted.
+
+CREATE INDEX IF NOT EXISTS idx_leads_sync ON leads(organization_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_contacts_sync ON contacts(organization_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_activities_sync ON activities(organization_id, updated_at, id);
+
+-- ============================================================================
+-- Tombstones
+-- ============================================================================
+-- Records deleted for good leave a tombstone, so that apps remove their
+-- copy. Activities are always deleted for good; leads and contacts when
+-- purged from the trash or anonymized away.
+
+CREATE TABLE IF NOT EXISTS crm_sync_tombstones (
+    id bigserial PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    entity varchar(20) NOT NULL,
+    record_id uuid NOT NULL,
+    deleted_at timestamptz NOT NULL DEFAULT now(),
+
+    CONSTRAINT crm_sync_tombstones_entity_check CHECK (entity IN ('leads', 'contacts', 'activities'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_crm_sync_tombstones_changes ON crm_sync_tombstones(organization_id, entity, deleted_at, record_id);
+
+CREATE OR REPLACE FUNCTION crm_sync_record_tombstone()
+RETURNS trigger AS $$
+BEGIN
+    INSERT INTO crm_sync_tombstones (organization_id, entity, record_id)
+    VALUES (OLD.organization_id, TG_ARGV[0], OLD.id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS leads_sync_tombstone ON leads;
+CREATE TRIGGER leads_sync_tombstone
+    AFTER DELETE ON leads
+    FOR EACH ROW
+    EXECUTE FUNCTION crm_sync_record_tombstone('leads');
+
+DROP TRIGGER IF EXISTS contacts_sync_tombstone ON contacts;
+CREATE TRIGGER contacts_sync_tombstone
+    AFTER DELETE ON contacts
+    FOR EACH ROW
+    EXECUTE FUNCTION crm_sync_record_tombstone('contacts');
+
+DROP TRIGGER IF EXISTS activities_sync_tombstone ON activities;
+CREATE TRIGGER activities_sync_tombstone
+    AFTER DELETE ON activities
+    FOR EACH ROW
+    EXECUTE FUNCTION crm_sync_record_tombstone('activities');
+
+-- ============================================================================
+-- Pushed Operations
+-- ============================================================================
+-- Each write the app pushes carries a client-generated ID. Applied ones are
+-- kept so that an operation resent after a dropped connection is not
+-- applied twice, and so that later operations can name a record created
+-- offline by the ID of the operation that created it.
+
+CREATE TABLE IF NOT EXISTS crm_sync_ops (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    client_id uuid NOT NULL,
+    user_id uuid,
+    entity varchar(20) NOT NULL,
+    action varchar(10) NOT NULL,
+    record_id uuid NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (organization_id, client_id),
+    CONSTRAINT crm_sync_ops_entity_check CHECK (entity IN ('leads', 'contacts', 'activities')),
+    CONSTRAINT crm_sync_ops_action_check CHECK (action IN ('create', 'update', 'delete'))
+);
@@ -0,0 +1,27 @@
+-- Migration: Fiscal Calendars
+-- Description: Per-organization fiscal year definitions (start month, monthly or 4-4-5 style week periods) that forecast, quota, budget and revenue analytics resolve fiscal period parameters through
+-- Version: 20250201000075
+
+-- ============================================================================
+-- Fiscal Calendars
+-- ============================================================================
+-- At most one per organization; without one, fiscal years are calendar
+-- years. Fiscal years are named after the calendar year they end in.
+-- Week patterns end each year on the last week_end day (0 is Sunday) of
+-- the month before start_month, or the one nearest its end.
+
+CREATE TABLE IF NOT EXISTS fiscal_calendars (
+    organization_id uuid PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    start_month smallint NOT NULL DEFAULT 1,
+    pattern varchar(10) NOT NULL DEFAULT 'monthly',
+    week_end smallint NOT NULL DEFAULT 6,
+    year_end varchar(10) NOT NULL DEFAULT 'last',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    updated_by uuid,
+
+    CONSTRAINT fiscal_calendars_start_month_check CHECK (start_month BETWEEN 1 AND 12),
+    CONSTRAINT fiscal_calendars_pattern_check CHECK (pattern IN ('monthly', '4-4-5', '4-5-4', '5-4-4')),
+    CONSTRAINT fiscal_calendars_week_end_check CHECK (week_end BETWEEN 0 AND 6),
+    CONSTRAINT fiscal_calendars_year_end_check CHECK (year_end IN ('last', 'nearest'))
+);
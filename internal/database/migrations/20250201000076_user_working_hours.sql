@@ -0,0 +1,23 @@
+-- Migration: User Working Hours
+-- Description: Per-user working-hours calendars in the user's time zone, so automatic assignment only picks users on shift
+-- Version: 20250201000076
+
+-- ============================================================================
+-- User Working Hours
+-- ============================================================================
+-- weekly_hours has the format of business_calendars.weekly_hours and is read
+-- in the user's timezone. Holidays come from holiday_set_id, or the holiday
+-- set of the organization's default business calendar when it is null.
+-- Users without working hours are on shift around the clock.
+
+CREATE TABLE IF NOT EXISTS user_working_hours (
+    organization_id uuid NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    timezone varchar(64) NOT NULL DEFAULT 'UTC',
+    weekly_hours jsonb NOT NULL,
+    holiday_set_id uuid REFERENCES business_holiday_sets(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (organization_id, user_id)
+);
@@ -0,0 +1,241 @@
+// Package selfcheck verifies at boot that the database schema and the
+// settings the server needs are in place, so a missing migration or
+// setting fails the start with a message saying what to do rather than
+// surfacing later as 500s
+package selfcheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqldialect "github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+// Mode is how the server treats self-check problems, as set by
+// STARTUP_SELF_CHECK
+type Mode string
+
+const (
+	// ModeEnforce refuses to start when a check fails
+	ModeEnforce Mode = "enforce"
+	// ModeWarn logs the problems and starts anyway
+	ModeWarn Mode = "warn"
+	// ModeOff skips the checks
+	ModeOff Mode = "off"
+)
+
+// ParseMode returns the mode named by a STARTUP_SELF_CHECK setting,
+// defaulting to enforce
+func ParseMode(name string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(name))) {
+	case "", ModeEnforce:
+		return ModeEnforce, nil
+	case ModeWarn:
+		return ModeWarn, nil
+	case ModeOff:
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("invalid startup self-check mode %q: must be one of enforce, warn, off", name)
+	}
+}
+
+// Requirements is what the server needs before it can serve
+type Requirements struct {
+	// Migration is the newest migration version the build expects applied
+	Migration uint
+	// Relations are the tables and views queried directly
+	Relations []string
+	// Extensions are the PostgreSQL extensions the schema relies on.
+	// They are only checked on PostgreSQL.
+	Extensions []string
+	// Settings are the environment variables that must be set
+	Settings []string
+}
+
+// Defaults returns the requirements of the server built with migrations up
+// to version migration
+func Defaults(migration uint) Requirements {
+	return Requirements{
+		Migration: migration,
+		Relations: []string{
+			"organizations",
+			"organization_users",
+			"casbin_rules",
+			"contacts",
+			"leads",
+			"lead_stages",
+			"crm_tags",
+			"attachments",
+			"assignment_rules",
+			// Read by assignment statistics; dropped views break them at query time
+			"assignment_stats_by_user",
+			"scheduled_job_runs",
+			"subscription_plans",
+			"usage_daily",
+		},
+		Extensions: []string{"uuid-ossp", "pg_trgm"},
+		Settings: []string{
+			"PORT",
+			"BLUEPRINT_DB_HOST",
+			"BLUEPRINT_DB_PORT",
+			"BLUEPRINT_DB_USERNAME",
+			"BLUEPRINT_DB_DATABASE",
+		},
+	}
+}
+
+// Problem is one failed check, with what to do about it
+type Problem struct {
+	Check   string
+	Message string
+	Fix     string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("[%s] %s: %s", p.Check, p.Message, p.Fix)
+}
+
+// Error lists every problem found, so they can all be fixed in one go
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("startup self-check found %d problem(s):", len(e.Problems)))
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Run checks the settings, then the database: that it is reachable, that
+// the migrations are applied and clean, and that the relations and
+// extensions exist. It returns an *Error listing every problem found. A
+// database ahead of the build is not a problem, so older instances keep
+// running during a rolling deploy.
+func Run(ctx context.Context, db *sql.DB, dialect sqldialect.Dialect, req Requirements, getenv func(string) string) error {
+	dialect = sqldialect.DialectOrDefault(dialect)
+	var problems []Problem
+
+	for _, name := range req.Settings {
+		if strings.TrimSpace(getenv(name)) == "" {
+			problems = append(problems, Problem{
+				Check:   "setting",
+				Message: name + " is not set",
+				Fix:     "set " + name + " in the environment or in .env",
+			})
+		}
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		problems = append(problems, Problem{
+			Check:   "database",
+			Message: fmt.Sprintf("the database is unreachable (%v)", err),
+			Fix:     "check the BLUEPRINT_DB_* settings and that the database is running",
+		})
+		return &Error{Problems: problems}
+	}
+
+	migrationProblems, err := checkMigrations(ctx, db, req.Migration)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, migrationProblems...)
+
+	for _, relation := range req.Relations {
+		var exists bool
+		if err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, relation).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check relation %s: %w", relation, err)
+		}
+		if !exists {
+			problems = append(problems, Problem{
+				Check:   "relation",
+				Message: "table or view " + relation + " does not exist",
+				Fix:     "apply the migrations in internal/database/migrations; if they are applied, the relation was dropped by hand and the migration creating it must be re-run",
+			})
+		}
+	}
+
+	if dialect == sqldialect.Postgres && len(req.Extensions) > 0 {
+		installed := make(map[string]bool)
+		rows, err := db.QueryContext(ctx, `SELECT extname FROM pg_extension`)
+		if err != nil {
+			return fmt.Errorf("failed to list extensions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return fmt.Errorf("failed to scan extension: %w", err)
+			}
+			installed[name] = true
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating extensions: %w", err)
+		}
+		for _, extension := range req.Extensions {
+			if !installed[extension] {
+				problems = append(problems, Problem{
+					Check:   "extension",
+					Message: "extension " + extension + " is not installed",
+					Fix:     fmt.Sprintf(`run CREATE EXTENSION IF NOT EXISTS "%s" as a superuser`, extension),
+				})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// checkMigrations compares the version recorded by golang-migrate in
+// schema_migrations with the version the build expects
+func checkMigrations(ctx context.Context, db *sql.DB, expected uint) ([]Problem, error) {
+	var tracked bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&tracked); err != nil {
+		return nil, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if !tracked {
+		return []Problem{{
+			Check:   "migrations",
+			Message: "no migrations have been applied",
+			Fix:     fmt.Sprintf("apply the migrations in internal/database/migrations up to version %d", expected),
+		}}, nil
+	}
+
+	var version uint
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []Problem{{
+			Check:   "migrations",
+			Message: "no migrations have been applied",
+			Fix:     fmt.Sprintf("apply the migrations in internal/database/migrations up to version %d", expected),
+		}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	switch {
+	case dirty:
+		return []Problem{{
+			Check:   "migrations",
+			Message: fmt.Sprintf("migration %d failed partway and left the schema dirty", version),
+			Fix:     fmt.Sprintf("repair what migration %d changed, run `migrate force %d`, then apply the remaining migrations", version, version),
+		}}, nil
+	case version < expected:
+		return []Problem{{
+			Check:   "migrations",
+			Message: fmt.Sprintf("the database is at migration %d but this build expects %d", version, expected),
+			Fix:     fmt.Sprintf("apply the pending migrations in internal/database/migrations up to version %d", expected),
+		}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,99 @@
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqldialect "github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestRunPassesWhenEverythingIsInPlace(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery(`to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	// A database ahead of the build is fine during a rolling deploy
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(62, false))
+	mock.ExpectQuery(`SELECT to_regclass\(\$1\)`).WithArgs("leads").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}).AddRow("plpgsql").AddRow("pg_trgm"))
+
+	req := Requirements{Migration: 61, Relations: []string{"leads"}, Extensions: []string{"pg_trgm"}, Settings: []string{"PORT"}}
+	require.NoError(t, Run(context.Background(), db, sqldialect.Postgres, req, env(map[string]string{"PORT": "8080"})))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunReportsEveryProblem(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery(`to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(40, false))
+	mock.ExpectQuery(`SELECT to_regclass\(\$1\)`).WithArgs("assignment_stats_by_user").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}).AddRow("plpgsql"))
+
+	req := Requirements{Migration: 61, Relations: []string{"assignment_stats_by_user"}, Extensions: []string{"pg_trgm"}, Settings: []string{"PORT"}}
+	err = Run(context.Background(), db, sqldialect.Postgres, req, env(nil))
+
+	var selfCheckErr *Error
+	require.True(t, errors.As(err, &selfCheckErr))
+	checks := make([]string, 0, len(selfCheckErr.Problems))
+	for _, problem := range selfCheckErr.Problems {
+		checks = append(checks, problem.Check)
+		assert.NotEmpty(t, problem.Fix)
+	}
+	assert.Equal(t, []string{"setting", "migrations", "relation", "extension"}, checks)
+	assert.Contains(t, err.Error(), "the database is at migration 40 but this build expects 61")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunReportsDirtyMigration(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing()
+	mock.ExpectQuery(`to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(61, true))
+
+	// Extensions are not checked outside PostgreSQL
+	err = Run(context.Background(), db, sqldialect.CockroachDB, Requirements{Migration: 61, Extensions: []string{"pg_trgm"}}, env(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migrate force 61")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunStopsWhenDatabaseIsUnreachable(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	err = Run(context.Background(), db, sqldialect.Postgres, Requirements{Migration: 61, Relations: []string{"leads"}}, env(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the database is unreachable")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": ModeEnforce, "Enforce": ModeEnforce, " warn ": ModeWarn, "off": ModeOff} {
+		mode, err := ParseMode(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, mode, name)
+	}
+	_, err := ParseMode("sometimes")
+	assert.Error(t, err)
+}
@@ -221,7 +221,7 @@ func (h *InvoiceHandler) RecordPayment(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
-	var req types.Payment
+	var req types.InvoicePayment
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
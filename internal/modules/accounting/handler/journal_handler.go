@@ -8,6 +8,7 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -90,6 +91,13 @@ func (h *JournalHandler) ListJournals(w http.ResponseWriter, r *http.Request, _
 		OrganizationID: orgID,
 	}
 
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.MatchMode = matchMode
+
 	// Optional filters
 	if companyIDStr := r.URL.Query().Get("company_id"); companyIDStr != "" {
 		companyID, err := uuid.Parse(companyIDStr)
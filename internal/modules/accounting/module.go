@@ -54,12 +54,7 @@ func (m *AccountingModule) Init(ctx context.Context, deps registry.Dependencies)
 	taxCalc := tax.NewCalculator(deps.DB)
 
 	// Create services with state machine and event bus support
-	var invoiceStateMachine *workflow.StateMachine
-	if deps.StateMachineFactory != nil {
-		if sm, exists := deps.StateMachineFactory.GetStateMachine("accounting.invoice"); exists {
-			invoiceStateMachine = sm
-		}
-	}
+	invoiceStateMachine, _ := m.getStateMachine(deps, "accounting.invoice")
 	invoiceService := service.NewInvoiceServiceWithDependencies(invoiceRepo, paymentRepo, taxCalc, invoiceStateMachine, deps.EventBus)
 	paymentService := service.NewPaymentService(paymentRepo)
 	accountService := service.NewAccountService(accountRepo)
@@ -228,13 +223,9 @@ func (m *AccountingModule) Health() error {
 }
 
 // getStateMachine helper function to retrieve state machines from dependencies
-func (m *AccountingModule) getStateMachine(deps registry.Dependencies, workflowID string) (interface{}, bool) {
+func (m *AccountingModule) getStateMachine(deps registry.Dependencies, workflowID string) (*workflow.StateMachine, bool) {
 	if deps.StateMachineFactory != nil {
-		if factory, ok := deps.StateMachineFactory.(interface {
-			GetStateMachine(workflowID string) (interface{}, bool)
-		}); ok {
-			return factory.GetStateMachine(workflowID)
-		}
+		return deps.StateMachineFactory.GetStateMachine(workflowID)
 	}
 	return nil, false
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -29,6 +30,7 @@ type AccountFilter struct {
 	Deprecated     *bool
 	Reconcile      *bool
 	Search         *string // Search in name or code
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -153,8 +155,8 @@ func (r *accountRepository) FindAll(ctx context.Context, filters AccountFilter)
 
 	if filters.Search != nil && *filters.Search != "" {
 		argCount++
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR code ILIKE $%d)", argCount, argCount)
-		searchPattern := "%" + *filters.Search + "%"
+		query += fmt.Sprintf(" AND (%s OR %s)", database.ILike("name", argCount), database.ILike("code", argCount))
+		searchPattern := database.LikePattern(*filters.Search, filters.MatchMode)
 		args = append(args, searchPattern)
 	}
 
@@ -199,7 +199,7 @@ func (r *invoiceRepository) findLinesByInvoiceID(ctx context.Context, invoiceID
 	return lines, nil
 }
 
-func (r *invoiceRepository) findPaymentsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]types.Payment, error) {
+func (r *invoiceRepository) findPaymentsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]types.InvoicePayment, error) {
 	query := `
 		SELECT id, organization_id, company_id, invoice_id, partner_id, payment_date,
 		 amount, currency_id, journal_id, payment_method, reference, note,
@@ -215,9 +215,9 @@ func (r *invoiceRepository) findPaymentsByInvoiceID(ctx context.Context, invoice
 	}
 	defer rows.Close()
 
-	var payments []types.Payment
+	var payments []types.InvoicePayment
 	for rows.Next() {
-		var payment types.Payment
+		var payment types.InvoicePayment
 		err = rows.Scan(
 			&payment.ID, &payment.OrganizationID, &payment.CompanyID, &payment.InvoiceID,
 			&payment.PartnerID, &payment.PaymentDate, &payment.Amount, &payment.CurrencyID,
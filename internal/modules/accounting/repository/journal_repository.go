@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -27,6 +28,7 @@ type JournalFilter struct {
 	Type           *string // sale, purchase, cash, bank, general
 	Active         *bool
 	Search         *string // Search in name or code
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -142,8 +144,8 @@ func (r *journalRepository) FindAll(ctx context.Context, filters JournalFilter)
 
 	if filters.Search != nil && *filters.Search != "" {
 		argCount++
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR code ILIKE $%d)", argCount, argCount)
-		searchPattern := "%" + *filters.Search + "%"
+		query += fmt.Sprintf(" AND (%s OR %s)", database.ILike("name", argCount), database.ILike("code", argCount))
+		searchPattern := database.LikePattern(*filters.Search, filters.MatchMode)
 		args = append(args, searchPattern)
 	}
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/accounting/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -27,6 +28,7 @@ type TaxFilter struct {
 	AmountType     *string // percent, fixed, division, group
 	Active         *bool
 	Search         *string // Search in name or description
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -146,8 +148,8 @@ func (r *taxRepository) FindAll(ctx context.Context, filters TaxFilter) ([]types
 
 	if filters.Search != nil && *filters.Search != "" {
 		argCount++
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", argCount, argCount)
-		searchPattern := "%" + *filters.Search + "%"
+		query += fmt.Sprintf(" AND (%s OR %s)", database.ILike("name", argCount), database.ILike("description", argCount))
+		searchPattern := database.LikePattern(*filters.Search, filters.MatchMode)
 		args = append(args, searchPattern)
 	}
 
@@ -229,7 +229,7 @@ func (s *InvoiceService) CancelInvoice(ctx context.Context, id uuid.UUID) (*type
 	return updatedInvoice, nil
 }
 
-func (s *InvoiceService) RecordPayment(ctx context.Context, invoiceID uuid.UUID, payment types.Payment) (*types.Invoice, error) {
+func (s *InvoiceService) RecordPayment(ctx context.Context, invoiceID uuid.UUID, payment types.InvoicePayment) (*types.Invoice, error) {
 	// Get the invoice
 	invoice, err := s.repo.FindByID(ctx, invoiceID)
 	if err != nil {
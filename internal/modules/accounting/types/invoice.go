@@ -23,30 +23,30 @@ const (
 )
 
 type Invoice struct {
-	ID               uuid.UUID     `json:"id" db:"id"`
-	OrganizationID   uuid.UUID     `json:"organization_id" db:"organization_id"`
-	CompanyID        uuid.UUID     `json:"company_id" db:"company_id"`
-	PartnerID        uuid.UUID     `json:"partner_id" db:"partner_id"`
-	Reference        string        `json:"reference" db:"reference"`
-	Status           InvoiceStatus `json:"status" db:"status"`
-	Type             InvoiceType   `json:"type" db:"type"`
-	InvoiceDate      time.Time     `json:"invoice_date" db:"invoice_date"`
-	DueDate          time.Time     `json:"due_date" db:"due_date"`
-	PaymentTermID    *uuid.UUID    `json:"payment_term_id,omitempty" db:"payment_term_id"`
-	FiscalPositionID *uuid.UUID    `json:"fiscal_position_id,omitempty" db:"fiscal_position_id"`
-	CurrencyID       uuid.UUID     `json:"currency_id" db:"currency_id"`
-	JournalID        uuid.UUID     `json:"journal_id" db:"journal_id"`
-	AmountUntaxed    float64       `json:"amount_untaxed" db:"amount_untaxed"`
-	AmountTax        float64       `json:"amount_tax" db:"amount_tax"`
-	AmountTotal      float64       `json:"amount_total" db:"amount_total"`
-	AmountResidual   float64       `json:"amount_residual" db:"amount_residual"`
-	Note             string        `json:"note" db:"note"`
-	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
-	CreatedBy        uuid.UUID     `json:"created_by" db:"created_by"`
-	UpdatedBy        uuid.UUID     `json:"updated_by" db:"updated_by"`
-	Lines            []InvoiceLine `json:"lines" db:"-"`
-	Payments         []Payment     `json:"payments" db:"-"`
+	ID               uuid.UUID        `json:"id" db:"id"`
+	OrganizationID   uuid.UUID        `json:"organization_id" db:"organization_id"`
+	CompanyID        uuid.UUID        `json:"company_id" db:"company_id"`
+	PartnerID        uuid.UUID        `json:"partner_id" db:"partner_id"`
+	Reference        string           `json:"reference" db:"reference"`
+	Status           InvoiceStatus    `json:"status" db:"status"`
+	Type             InvoiceType      `json:"type" db:"type"`
+	InvoiceDate      time.Time        `json:"invoice_date" db:"invoice_date"`
+	DueDate          time.Time        `json:"due_date" db:"due_date"`
+	PaymentTermID    *uuid.UUID       `json:"payment_term_id,omitempty" db:"payment_term_id"`
+	FiscalPositionID *uuid.UUID       `json:"fiscal_position_id,omitempty" db:"fiscal_position_id"`
+	CurrencyID       uuid.UUID        `json:"currency_id" db:"currency_id"`
+	JournalID        uuid.UUID        `json:"journal_id" db:"journal_id"`
+	AmountUntaxed    float64          `json:"amount_untaxed" db:"amount_untaxed"`
+	AmountTax        float64          `json:"amount_tax" db:"amount_tax"`
+	AmountTotal      float64          `json:"amount_total" db:"amount_total"`
+	AmountResidual   float64          `json:"amount_residual" db:"amount_residual"`
+	Note             string           `json:"note" db:"note"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at"`
+	CreatedBy        uuid.UUID        `json:"created_by" db:"created_by"`
+	UpdatedBy        uuid.UUID        `json:"updated_by" db:"updated_by"`
+	Lines            []InvoiceLine    `json:"lines" db:"-"`
+	Payments         []InvoicePayment `json:"payments" db:"-"`
 }
 
 type InvoiceLine struct {
@@ -69,7 +69,7 @@ type InvoiceLine struct {
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
 
-type Payment struct {
+type InvoicePayment struct {
 	ID             uuid.UUID `json:"id" db:"id"`
 	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
 	CompanyID      uuid.UUID `json:"company_id" db:"company_id"`
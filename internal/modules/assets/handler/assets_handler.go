@@ -0,0 +1,505 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AssetsHandler handles asset categories, the fixed asset register,
+// depreciation runs, disposals and transfers
+type AssetsHandler struct {
+	service *service.AssetsService
+}
+
+func NewAssetsHandler(service *service.AssetsService) *AssetsHandler {
+	return &AssetsHandler{service: service}
+}
+
+func (h *AssetsHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/asset-categories", h.ListCategories)
+	router.POST("/api/asset-categories", h.CreateCategory)
+	router.GET("/api/asset-categories/:id", h.GetCategory)
+	router.PUT("/api/asset-categories/:id", h.UpdateCategory)
+	router.DELETE("/api/asset-categories/:id", h.DeleteCategory)
+
+	router.GET("/api/assets", h.ListAssets)
+	router.POST("/api/assets", h.CreateAsset)
+	router.GET("/api/assets/:id", h.GetAsset)
+	router.PUT("/api/assets/:id", h.UpdateAsset)
+	router.DELETE("/api/assets/:id", h.DeleteAsset)
+	router.POST("/api/assets/:id/confirm", h.ConfirmAsset)
+	router.GET("/api/assets/:id/schedule", h.GetSchedule)
+	router.POST("/api/assets/:id/dispose", h.Dispose)
+	router.POST("/api/assets/:id/transfer", h.Transfer)
+	router.GET("/api/assets/:id/transfers", h.ListTransfers)
+
+	router.POST("/api/asset-depreciation/run", h.RunDepreciation)
+	router.GET("/api/asset-register", h.Register)
+}
+
+// ListCategories handles GET /api/asset-categories
+func (h *AssetsHandler) ListCategories(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	categories, err := h.service.ListCategories(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, categories)
+}
+
+// CreateCategory handles POST /api/asset-categories
+func (h *AssetsHandler) CreateCategory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.CreateCategory(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, category)
+}
+
+// GetCategory handles GET /api/asset-categories/:id
+func (h *AssetsHandler) GetCategory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.GetCategory(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// UpdateCategory handles PUT /api/asset-categories/:id
+func (h *AssetsHandler) UpdateCategory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.UpdateCategory(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /api/asset-categories/:id
+func (h *AssetsHandler) DeleteCategory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCategory(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAssets handles GET /api/assets with optional category_id,
+// department_id, state, q, limit and offset filters
+func (h *AssetsHandler) ListAssets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.AssetFilter{
+		OrganizationID: authCtx.OrganizationID,
+		State:          types.State(q.Get("state")),
+		Search:         q.Get("q"),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"category_id": &filter.CategoryID, "department_id": &filter.DepartmentID}) {
+		return
+	}
+
+	assets, err := h.service.ListAssets(r.Context(), filter)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, assets)
+}
+
+// CreateAsset handles POST /api/assets. Assets acquired from a vendor bill
+// pass its line as bill_line_id.
+func (h *AssetsHandler) CreateAsset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.AssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.service.CreateAsset(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, asset)
+}
+
+// GetAsset handles GET /api/assets/:id
+func (h *AssetsHandler) GetAsset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.service.GetAsset(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// UpdateAsset handles PUT /api/assets/:id
+func (h *AssetsHandler) UpdateAsset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.AssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.service.UpdateAsset(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// DeleteAsset handles DELETE /api/assets/:id
+func (h *AssetsHandler) DeleteAsset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteAsset(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ConfirmAsset handles POST /api/assets/:id/confirm
+func (h *AssetsHandler) ConfirmAsset(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.service.ConfirmAsset(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// GetSchedule handles GET /api/assets/:id/schedule
+func (h *AssetsHandler) GetSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	lines, err := h.service.GetSchedule(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lines)
+}
+
+// Dispose handles POST /api/assets/:id/dispose
+func (h *AssetsHandler) Dispose(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DisposalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	asset, err := h.service.Dispose(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// Transfer handles POST /api/assets/:id/transfer
+func (h *AssetsHandler) Transfer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.Transfer(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, transfer)
+}
+
+// ListTransfers handles GET /api/assets/:id/transfers
+func (h *AssetsHandler) ListTransfers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid asset ID", http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := h.service.ListTransfers(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+// RunDepreciation handles POST /api/asset-depreciation/run, posting
+// depreciation due up to an optional up_to date without waiting for the
+// scheduled run
+func (h *AssetsHandler) RunDepreciation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var upTo *time.Time
+	if v := r.URL.Query().Get("up_to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid up_to date", http.StatusBadRequest)
+			return
+		}
+		upTo = &t
+	}
+
+	result, err := h.service.RunDepreciation(r.Context(), authCtx.OrganizationID, authCtx.UserID, upTo)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Register handles GET /api/asset-register with optional from, to,
+// category_id and department_id filters
+func (h *AssetsHandler) Register(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.RegisterFilter{OrganizationID: authCtx.OrganizationID}
+	q := r.URL.Query()
+	for name, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = t
+		}
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"category_id": &filter.CategoryID, "department_id": &filter.DepartmentID}) {
+		return
+	}
+
+	register, err := h.service.Register(r.Context(), filter)
+	if err != nil {
+		writeAssetsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, register)
+}
+
+// queryUUIDs parses optional ID query parameters
+func queryUUIDs(w http.ResponseWriter, r *http.Request, params map[string]**uuid.UUID) bool {
+	q := r.URL.Query()
+	for name, dest := range params {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return false
+			}
+			*dest = &id
+		}
+	}
+	return true
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAssetsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrBillLineUsed),
+		errors.Is(err, repository.ErrCategoryInUse), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
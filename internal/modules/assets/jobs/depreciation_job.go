@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeAssetsDepreciation = "assets.depreciation"
+
+// DepreciationJobHandler handles queued depreciation runs
+type DepreciationJobHandler struct {
+	assetsService *service.AssetsService
+}
+
+func NewDepreciationJobHandler(assetsService *service.AssetsService) *DepreciationJobHandler {
+	return &DepreciationJobHandler{
+		assetsService: assetsService,
+	}
+}
+
+// Handle processes a depreciation job
+func (h *DepreciationJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.assetsService.RunAll(ctx); err != nil {
+		return fmt.Errorf("failed to post depreciation: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DepreciationJobHandler) JobType() string {
+	return JobTypeAssetsDepreciation
+}
+
+// Scheduler posts due depreciation on a fixed interval, so each month's
+// entries reach the books at month end without anyone asking
+type Scheduler struct {
+	handler     *DepreciationJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DepreciationJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start posts due depreciation every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeAssetsDepreciation, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeAssetsDepreciation, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled depreciation run failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule depreciation run", "error", err)
+	}
+}
@@ -0,0 +1,871 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a category, asset, bill line, account,
+	// journal or department does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a category name or asset code is already used
+	ErrDuplicate = errors.New("already used")
+	// ErrBillLineUsed is returned when a bill line already backs an asset
+	ErrBillLineUsed = errors.New("bill line already backs an asset")
+	// ErrCategoryInUse is returned when deleting a category that still has assets
+	ErrCategoryInUse = errors.New("asset category has assets")
+	// ErrInvalidState is returned when the asset's state does not allow the change
+	ErrInvalidState = errors.New("asset state does not allow this")
+)
+
+// AssetsRepo defines the interface for fixed asset repository operations
+type AssetsRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListCategories(ctx context.Context, orgID uuid.UUID) ([]types.Category, error)
+	FindCategory(ctx context.Context, orgID, id uuid.UUID) (*types.Category, error)
+	CreateCategory(ctx context.Context, orgID, userID uuid.UUID, request types.CategoryRequest) (*types.Category, error)
+	UpdateCategory(ctx context.Context, orgID, userID, id uuid.UUID, request types.CategoryRequest) (*types.Category, error)
+	DeleteCategory(ctx context.Context, orgID, id uuid.UUID) error
+	FindBillLine(ctx context.Context, orgID, id uuid.UUID) (*types.BillLine, error)
+	ListAssets(ctx context.Context, filter types.AssetFilter) ([]types.Asset, error)
+	FindAsset(ctx context.Context, orgID, id uuid.UUID) (*types.Asset, error)
+	CreateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error)
+	UpdateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error)
+	DeleteAsset(ctx context.Context, orgID, id uuid.UUID) error
+	Confirm(ctx context.Context, orgID, userID, id uuid.UUID, lines []types.DepreciationLine) (*types.Asset, error)
+	Schedule(ctx context.Context, orgID, id uuid.UUID) ([]types.DepreciationLine, error)
+	DueDepreciation(ctx context.Context, orgID uuid.UUID, upTo time.Time, assetID *uuid.UUID) ([]types.DueDepreciation, error)
+	PostDepreciation(ctx context.Context, due types.DueDepreciation, userID *uuid.UUID, at time.Time) (*uuid.UUID, error)
+	Dispose(ctx context.Context, orgID, userID, id uuid.UUID, disposal types.Disposal) (*types.Asset, error)
+	Transfer(ctx context.Context, orgID, userID, id uuid.UUID, request types.TransferRequest) (*types.Transfer, error)
+	ListTransfers(ctx context.Context, orgID, id uuid.UUID) ([]types.Transfer, error)
+	Register(ctx context.Context, filter types.RegisterFilter) ([]types.RegisterLine, error)
+}
+
+// AssetsRepository stores the fixed asset register and posts its
+// depreciation and disposal entries to the journal
+type AssetsRepository struct {
+	db *sql.DB
+}
+
+// Ensure AssetsRepository implements AssetsRepo interface
+var _ AssetsRepo = &AssetsRepository{}
+
+func NewAssetsRepository(db *sql.DB) *AssetsRepository {
+	return &AssetsRepository{db: db}
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// ListOrganizationIDs returns the organizations with running assets
+func (r *AssetsRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT organization_id FROM fixed_assets WHERE state = 'running'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const categoryColumns = `
+	id, organization_id, name, method, useful_life_months, declining_rate, journal_id, asset_account_id,
+	depreciation_account_id, expense_account_id, gain_account_id, loss_account_id, active, created_at, updated_at
+`
+
+func scanCategory(row interface{ Scan(...interface{}) error }) (*types.Category, error) {
+	var c types.Category
+	var gainID, lossID uuid.NullUUID
+	err := row.Scan(&c.ID, &c.OrganizationID, &c.Name, &c.Method, &c.UsefulLifeMonths, &c.DecliningRate, &c.JournalID,
+		&c.AssetAccountID, &c.DepreciationAccountID, &c.ExpenseAccountID, &gainID, &lossID, &c.Active,
+		&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.GainAccountID = nullUUID(gainID)
+	c.LossAccountID = nullUUID(lossID)
+	return &c, nil
+}
+
+func (r *AssetsRepository) ListCategories(ctx context.Context, orgID uuid.UUID) ([]types.Category, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+categoryColumns+` FROM asset_categories WHERE organization_id = $1 ORDER BY name`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list asset categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []types.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan asset category: %w", err)
+		}
+		categories = append(categories, *c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *AssetsRepository) FindCategory(ctx context.Context, orgID, id uuid.UUID) (*types.Category, error) {
+	c, err := scanCategory(r.db.QueryRowContext(ctx, `
+		SELECT `+categoryColumns+` FROM asset_categories WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("asset category %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find asset category: %w", err)
+	}
+	return c, nil
+}
+
+func (r *AssetsRepository) CreateCategory(ctx context.Context, orgID, userID uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	if err := r.checkCategoryRefs(ctx, orgID, request); err != nil {
+		return nil, err
+	}
+	c, err := scanCategory(r.db.QueryRowContext(ctx, `
+		INSERT INTO asset_categories (
+			organization_id, name, method, useful_life_months, declining_rate, journal_id, asset_account_id,
+			depreciation_account_id, expense_account_id, gain_account_id, loss_account_id, active, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13)
+		RETURNING `+categoryColumns,
+		orgID, request.Name, request.Method, request.UsefulLifeMonths, request.DecliningRate, request.JournalID,
+		request.AssetAccountID, request.DepreciationAccountID, request.ExpenseAccountID, request.GainAccountID,
+		request.LossAccountID, request.Active == nil || *request.Active, userID))
+	if err != nil {
+		if isUniqueViolation(err, "asset_categories_name_unique") {
+			return nil, fmt.Errorf("asset category name %s %w", request.Name, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create asset category: %w", err)
+	}
+	return c, nil
+}
+
+// UpdateCategory replaces a category. Assets keep the method and useful
+// life they were created with, but post to the category's new accounts.
+func (r *AssetsRepository) UpdateCategory(ctx context.Context, orgID, userID, id uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	if err := r.checkCategoryRefs(ctx, orgID, request); err != nil {
+		return nil, err
+	}
+	c, err := scanCategory(r.db.QueryRowContext(ctx, `
+		UPDATE asset_categories SET
+			name = $3, method = $4, useful_life_months = $5, declining_rate = $6, journal_id = $7,
+			asset_account_id = $8, depreciation_account_id = $9, expense_account_id = $10, gain_account_id = $11,
+			loss_account_id = $12, active = $13, updated_by = $14, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+categoryColumns,
+		orgID, id, request.Name, request.Method, request.UsefulLifeMonths, request.DecliningRate, request.JournalID,
+		request.AssetAccountID, request.DepreciationAccountID, request.ExpenseAccountID, request.GainAccountID,
+		request.LossAccountID, request.Active == nil || *request.Active, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("asset category %w", ErrNotFound)
+		}
+		if isUniqueViolation(err, "asset_categories_name_unique") {
+			return nil, fmt.Errorf("asset category name %s %w", request.Name, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update asset category: %w", err)
+	}
+	return c, nil
+}
+
+// checkCategoryRefs makes sure the category's journal and accounts belong
+// to the organization
+func (r *AssetsRepository) checkCategoryRefs(ctx context.Context, orgID uuid.UUID, request types.CategoryRequest) error {
+	var journalExists bool
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM account_journals WHERE id = $1 AND organization_id = $2 AND active)
+	`, request.JournalID, orgID).Scan(&journalExists); err != nil {
+		return fmt.Errorf("failed to check journal: %w", err)
+	}
+	if !journalExists {
+		return fmt.Errorf("journal %w", ErrNotFound)
+	}
+
+	ids := map[uuid.UUID]bool{request.AssetAccountID: true, request.DepreciationAccountID: true, request.ExpenseAccountID: true}
+	for _, id := range []*uuid.UUID{request.GainAccountID, request.LossAccountID} {
+		if id != nil {
+			ids[*id] = true
+		}
+	}
+	accountIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		accountIDs = append(accountIDs, id.String())
+	}
+	var found int
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM account_accounts WHERE organization_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL
+	`, orgID, pq.Array(accountIDs)).Scan(&found); err != nil {
+		return fmt.Errorf("failed to check accounts: %w", err)
+	}
+	if found != len(accountIDs) {
+		return fmt.Errorf("account %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (r *AssetsRepository) DeleteCategory(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM asset_categories WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrCategoryInUse
+		}
+		return fmt.Errorf("failed to delete asset category: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("asset category %w", ErrNotFound)
+	}
+	return nil
+}
+
+// FindBillLine returns a line of a posted vendor bill
+func (r *AssetsRepository) FindBillLine(ctx context.Context, orgID, id uuid.UUID) (*types.BillLine, error) {
+	var l types.BillLine
+	err := r.db.QueryRowContext(ctx, `
+		SELECT l.id, m.id, m.partner_id, COALESCE(l.name, ''), COALESCE(m.invoice_date, m.date),
+			CASE WHEN COALESCE(l.price_subtotal, 0) > 0 THEN l.price_subtotal ELSE COALESCE(l.debit, 0) END
+		FROM invoice_lines l
+		JOIN invoices m ON m.id = l.move_id
+		WHERE l.organization_id = $1 AND l.id = $2 AND m.move_type = 'in_invoice' AND m.state = 'posted'
+			AND l.deleted_at IS NULL AND m.deleted_at IS NULL
+			AND (l.display_type IS NULL OR l.display_type = 'product')
+	`, orgID, id).Scan(&l.ID, &l.BillID, &l.VendorID, &l.Name, &l.InvoiceDate, &l.Amount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("posted vendor bill line %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find vendor bill line: %w", err)
+	}
+	return &l, nil
+}
+
+const assetColumns = `
+	a.id, a.organization_id, a.category_id, c.name, COALESCE(a.code, ''), a.name, a.vendor_id, a.bill_id,
+	a.bill_line_id, a.acquisition_date, a.depreciation_start, a.cost, a.salvage_value, a.method,
+	a.useful_life_months, a.declining_rate, a.department_id, COALESCE(a.location, ''), a.custodian_id, a.state,
+	a.accumulated_depreciation, a.disposal_date, a.disposal_proceeds, a.disposal_gain_loss, a.disposal_move_id,
+	COALESCE(a.notes, ''), a.created_at, a.updated_at
+`
+
+const assetFrom = ` FROM fixed_assets a JOIN asset_categories c ON c.id = a.category_id `
+
+func scanAsset(row interface{ Scan(...interface{}) error }) (*types.Asset, error) {
+	var a types.Asset
+	var vendorID, billID, billLineID, departmentID, custodianID, disposalMoveID uuid.NullUUID
+	var disposalDate sql.NullTime
+	var proceeds, gainLoss sql.NullFloat64
+	err := row.Scan(&a.ID, &a.OrganizationID, &a.CategoryID, &a.CategoryName, &a.Code, &a.Name, &vendorID, &billID,
+		&billLineID, &a.AcquisitionDate, &a.DepreciationStart, &a.Cost, &a.SalvageValue, &a.Method,
+		&a.UsefulLifeMonths, &a.DecliningRate, &departmentID, &a.Location, &custodianID, &a.State,
+		&a.Accumulated, &disposalDate, &proceeds, &gainLoss, &disposalMoveID, &a.Notes, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.VendorID = nullUUID(vendorID)
+	a.BillID = nullUUID(billID)
+	a.BillLineID = nullUUID(billLineID)
+	a.DepartmentID = nullUUID(departmentID)
+	a.CustodianID = nullUUID(custodianID)
+	a.DisposalMoveID = nullUUID(disposalMoveID)
+	if disposalDate.Valid {
+		a.DisposalDate = &disposalDate.Time
+	}
+	if proceeds.Valid {
+		a.DisposalProceeds = &proceeds.Float64
+	}
+	if gainLoss.Valid {
+		a.DisposalGainLoss = &gainLoss.Float64
+	}
+	if a.State != types.StateDisposed {
+		a.BookValue = a.Cost - a.Accumulated
+	}
+	return &a, nil
+}
+
+func (r *AssetsRepository) ListAssets(ctx context.Context, filter types.AssetFilter) ([]types.Asset, error) {
+	where := []string{"a.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.CategoryID != nil {
+		add("a.category_id = $%d", *filter.CategoryID)
+	}
+	if filter.DepartmentID != nil {
+		add("a.department_id = $%d", *filter.DepartmentID)
+	}
+	if filter.State != "" {
+		add("a.state = $%d", string(filter.State))
+	}
+	if filter.Search != "" {
+		args = append(args, database.LikePattern(filter.Search, database.MatchModeContains))
+		where = append(where, "("+database.ILike("a.name", len(args))+" OR "+database.ILike("a.code", len(args))+")")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+assetColumns+assetFrom+` WHERE `+strings.Join(where, " AND ")+
+		fmt.Sprintf(` ORDER BY a.acquisition_date DESC, a.name LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assets: %w", err)
+	}
+	defer rows.Close()
+
+	assets := []types.Asset{}
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan asset: %w", err)
+		}
+		assets = append(assets, *a)
+	}
+	return assets, rows.Err()
+}
+
+func (r *AssetsRepository) FindAsset(ctx context.Context, orgID, id uuid.UUID) (*types.Asset, error) {
+	a, err := scanAsset(r.db.QueryRowContext(ctx, `SELECT `+assetColumns+assetFrom+` WHERE a.organization_id = $1 AND a.id = $2`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("asset %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find asset: %w", err)
+	}
+	return a, nil
+}
+
+func checkDepartment(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM departments WHERE id = $1 AND organization_id = $2)
+	`, *id, orgID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check department: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("department %w", ErrNotFound)
+	}
+	return nil
+}
+
+func assetWriteError(err error, asset types.Asset) error {
+	switch {
+	case isUniqueViolation(err, "fixed_assets_code_unique"):
+		return fmt.Errorf("asset code %s %w", asset.Code, ErrDuplicate)
+	case isUniqueViolation(err, "fixed_assets_bill_line_unique"):
+		return ErrBillLineUsed
+	}
+	return fmt.Errorf("failed to save asset: %w", err)
+}
+
+func (r *AssetsRepository) CreateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error) {
+	if err := checkDepartment(ctx, r.db, asset.OrganizationID, asset.DepartmentID); err != nil {
+		return nil, err
+	}
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO fixed_assets (
+			organization_id, category_id, code, name, vendor_id, bill_id, bill_line_id, acquisition_date,
+			depreciation_start, cost, salvage_value, method, useful_life_months, declining_rate, department_id,
+			location, custodian_id, notes, created_by, updated_by
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8::date, $9::date, $10, $11, $12, $13, $14, $15,
+			NULLIF($16, ''), $17, NULLIF($18, ''), $19, $19)
+		RETURNING id
+	`, asset.OrganizationID, asset.CategoryID, asset.Code, asset.Name, asset.VendorID, asset.BillID, asset.BillLineID,
+		asset.AcquisitionDate, asset.DepreciationStart, asset.Cost, asset.SalvageValue, asset.Method,
+		asset.UsefulLifeMonths, asset.DecliningRate, asset.DepartmentID, asset.Location, asset.CustodianID,
+		asset.Notes, userID).Scan(&id)
+	if err != nil {
+		return nil, assetWriteError(err, asset)
+	}
+	return r.FindAsset(ctx, asset.OrganizationID, id)
+}
+
+// UpdateAsset replaces a draft asset
+func (r *AssetsRepository) UpdateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error) {
+	if err := checkDepartment(ctx, r.db, asset.OrganizationID, asset.DepartmentID); err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE fixed_assets SET
+			category_id = $3, code = NULLIF($4, ''), name = $5, vendor_id = $6, bill_id = $7, bill_line_id = $8,
+			acquisition_date = $9::date, depreciation_start = $10::date, cost = $11, salvage_value = $12, method = $13,
+			useful_life_months = $14, declining_rate = $15, department_id = $16, location = NULLIF($17, ''),
+			custodian_id = $18, notes = NULLIF($19, ''), updated_by = $20, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'draft'
+	`, asset.OrganizationID, asset.ID, asset.CategoryID, asset.Code, asset.Name, asset.VendorID, asset.BillID,
+		asset.BillLineID, asset.AcquisitionDate, asset.DepreciationStart, asset.Cost, asset.SalvageValue, asset.Method,
+		asset.UsefulLifeMonths, asset.DecliningRate, asset.DepartmentID, asset.Location, asset.CustodianID,
+		asset.Notes, userID)
+	if err != nil {
+		return nil, assetWriteError(err, asset)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.FindAsset(ctx, asset.OrganizationID, asset.ID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: only draft assets can be edited", ErrInvalidState)
+	}
+	return r.FindAsset(ctx, asset.OrganizationID, asset.ID)
+}
+
+// DeleteAsset removes a draft asset
+func (r *AssetsRepository) DeleteAsset(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM fixed_assets WHERE organization_id = $1 AND id = $2 AND state = 'draft'`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.FindAsset(ctx, orgID, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: only draft assets can be deleted", ErrInvalidState)
+	}
+	return nil
+}
+
+// Confirm stores the depreciation schedule of a draft asset and starts
+// running it
+func (r *AssetsRepository) Confirm(ctx context.Context, orgID, userID, id uuid.UUID, lines []types.DepreciationLine) (*types.Asset, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	state := types.StateRunning
+	if len(lines) == 0 {
+		// Nothing to depreciate, such as an asset costing its salvage value
+		state = types.StateFullyDepreciated
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE fixed_assets SET state = $3, updated_by = $4, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'draft'
+	`, orgID, id, state, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm asset: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.FindAsset(ctx, orgID, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: asset is already confirmed", ErrInvalidState)
+	}
+
+	for _, l := range lines {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO asset_depreciation_lines (
+				organization_id, asset_id, sequence, period_date, amount, accumulated, book_value
+			) VALUES ($1, $2, $3, $4::date, $5, $6, $7)
+		`, orgID, id, l.Sequence, l.PeriodDate, l.Amount, l.Accumulated, l.BookValue); err != nil {
+			return nil, fmt.Errorf("failed to save depreciation line: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit asset confirmation: %w", err)
+	}
+	return r.FindAsset(ctx, orgID, id)
+}
+
+const lineColumns = `d.id, d.asset_id, d.sequence, d.period_date, d.amount, d.accumulated, d.book_value, d.move_id, d.posted_at`
+
+func scanLine(row interface{ Scan(...interface{}) error }, dest ...interface{}) (*types.DepreciationLine, error) {
+	var l types.DepreciationLine
+	var moveID uuid.NullUUID
+	var postedAt sql.NullTime
+	err := row.Scan(append([]interface{}{&l.ID, &l.AssetID, &l.Sequence, &l.PeriodDate, &l.Amount, &l.Accumulated,
+		&l.BookValue, &moveID, &postedAt}, dest...)...)
+	if err != nil {
+		return nil, err
+	}
+	l.MoveID = nullUUID(moveID)
+	if postedAt.Valid {
+		l.PostedAt = &postedAt.Time
+	}
+	return &l, nil
+}
+
+// Schedule returns an asset's depreciation lines, posted or not
+func (r *AssetsRepository) Schedule(ctx context.Context, orgID, id uuid.UUID) ([]types.DepreciationLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+lineColumns+` FROM asset_depreciation_lines d
+		WHERE d.organization_id = $1 AND d.asset_id = $2
+		ORDER BY d.sequence
+	`, orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list depreciation lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []types.DepreciationLine{}
+	for rows.Next() {
+		l, err := scanLine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan depreciation line: %w", err)
+		}
+		lines = append(lines, *l)
+	}
+	return lines, rows.Err()
+}
+
+// DueDepreciation returns the unposted lines of running assets dated up to
+// upTo, oldest first
+func (r *AssetsRepository) DueDepreciation(ctx context.Context, orgID uuid.UUID, upTo time.Time, assetID *uuid.UUID) ([]types.DueDepreciation, error) {
+	args := []interface{}{orgID, upTo}
+	query := `
+		SELECT ` + lineColumns + `, a.name, COALESCE(a.code, ''), c.journal_id, c.depreciation_account_id,
+			c.expense_account_id, dep.analytic_account_id,
+			d.sequence = (SELECT max(sequence) FROM asset_depreciation_lines WHERE asset_id = d.asset_id)
+		FROM asset_depreciation_lines d
+		JOIN fixed_assets a ON a.id = d.asset_id
+		JOIN asset_categories c ON c.id = a.category_id
+		LEFT JOIN departments dep ON dep.id = a.department_id
+		WHERE d.organization_id = $1 AND d.move_id IS NULL AND d.period_date <= $2::date AND a.state = 'running'`
+	if assetID != nil {
+		args = append(args, *assetID)
+		query += ` AND a.id = $3`
+	}
+	query += ` ORDER BY d.period_date, a.name, d.sequence`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due depreciation: %w", err)
+	}
+	defer rows.Close()
+
+	var due []types.DueDepreciation
+	for rows.Next() {
+		d := types.DueDepreciation{OrganizationID: orgID}
+		var analyticID uuid.NullUUID
+		l, err := scanLine(rows, &d.AssetName, &d.AssetCode, &d.JournalID, &d.DepreciationAccountID,
+			&d.ExpenseAccountID, &analyticID, &d.Last)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan due depreciation: %w", err)
+		}
+		d.Line = *l
+		d.DepartmentAnalyticID = nullUUID(analyticID)
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// PostDepreciation posts a schedule line as a journal entry debiting the
+// expense account, tagged with the asset's department, and crediting
+// accumulated depreciation. It returns nil when the line was posted by
+// someone else in the meantime.
+func (r *AssetsRepository) PostDepreciation(ctx context.Context, due types.DueDepreciation, userID *uuid.UUID, at time.Time) (*uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lineID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM asset_depreciation_lines WHERE id = $1 AND move_id IS NULL FOR UPDATE
+	`, due.Line.ID).Scan(&lineID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock depreciation line: %w", err)
+	}
+
+	label := due.AssetName
+	if due.AssetCode != "" {
+		label = due.AssetCode + " " + due.AssetName
+	}
+	name := fmt.Sprintf("Depreciation %s %s", label, due.Line.PeriodDate.Format("2006-01"))
+	moveID, err := insertEntry(ctx, tx, due.OrganizationID, due.JournalID, due.Line.PeriodDate, name, userID, []types.EntryLine{
+		{AccountID: due.ExpenseAccountID, Name: name, Debit: due.Line.Amount, AnalyticAccountID: due.DepartmentAnalyticID},
+		{AccountID: due.DepreciationAccountID, Name: name, Credit: due.Line.Amount},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE asset_depreciation_lines SET move_id = $2, posted_at = $3 WHERE id = $1
+	`, lineID, moveID, at); err != nil {
+		return nil, fmt.Errorf("failed to mark depreciation line posted: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE fixed_assets SET
+			accumulated_depreciation = accumulated_depreciation + $2,
+			state = CASE WHEN $3 THEN 'fully_depreciated' ELSE state END,
+			updated_at = now()
+		WHERE id = $1
+	`, due.Line.AssetID, due.Line.Amount, due.Last); err != nil {
+		return nil, fmt.Errorf("failed to update accumulated depreciation: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit depreciation entry: %w", err)
+	}
+	return &moveID, nil
+}
+
+// insertEntry creates a posted journal entry from balanced lines
+func insertEntry(ctx context.Context, tx *sql.Tx, orgID, journalID uuid.UUID, date time.Time, ref string, userID *uuid.UUID, lines []types.EntryLine) (uuid.UUID, error) {
+	var debit, credit float64
+	for _, l := range lines {
+		debit += l.Debit
+		credit += l.Credit
+	}
+	if fmt.Sprintf("%.2f", debit) != fmt.Sprintf("%.2f", credit) {
+		return uuid.Nil, fmt.Errorf("journal entry %s is unbalanced: debit %.2f, credit %.2f", ref, debit, credit)
+	}
+
+	var moveID uuid.UUID
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO invoices (
+			organization_id, move_type, date, ref, state, journal_id, amount_untaxed, amount_total,
+			amount_untaxed_signed, amount_total_signed, user_id, created_by
+		) VALUES ($1, 'entry', $2::date, $3, 'posted', $4, $5, $5, $5, $5, $6, $6)
+		RETURNING id
+	`, orgID, date, ref, journalID, debit, userID).Scan(&moveID); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create journal entry: %w", err)
+	}
+	for i, l := range lines {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO invoice_lines (
+				organization_id, move_id, sequence, name, account_id, debit, credit, balance, analytic_account_id,
+				exclude_from_invoice_tab, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $6 - $7, $8, true, $9)
+		`, orgID, moveID, (i+1)*10, l.Name, l.AccountID, l.Debit, l.Credit, l.AnalyticAccountID, userID); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create journal entry line: %w", err)
+		}
+	}
+	return moveID, nil
+}
+
+// Dispose posts the disposal entry, drops the schedule lines not yet
+// posted and closes the asset
+func (r *AssetsRepository) Dispose(ctx context.Context, orgID, userID, id uuid.UUID, disposal types.Disposal) (*types.Asset, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state types.State
+	var accumulated float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT state, accumulated_depreciation FROM fixed_assets WHERE organization_id = $1 AND id = $2 FOR UPDATE
+	`, orgID, id).Scan(&state, &accumulated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("asset %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock asset: %w", err)
+	}
+	if state != types.StateRunning && state != types.StateFullyDepreciated {
+		return nil, fmt.Errorf("%w: only confirmed assets can be disposed of", ErrInvalidState)
+	}
+	if fmt.Sprintf("%.2f", accumulated) != fmt.Sprintf("%.2f", disposal.Accumulated) {
+		return nil, fmt.Errorf("%w: depreciation was posted during the disposal, retry", ErrInvalidState)
+	}
+
+	moveID, err := insertEntry(ctx, tx, orgID, disposal.JournalID, disposal.Date, disposal.Ref, &userID, disposal.Lines)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM asset_depreciation_lines WHERE asset_id = $1 AND move_id IS NULL
+	`, id); err != nil {
+		return nil, fmt.Errorf("failed to drop depreciation lines: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE fixed_assets SET
+			state = 'disposed', disposal_date = $2::date, disposal_proceeds = $3, disposal_gain_loss = $4,
+			disposal_move_id = $5, updated_by = $6, updated_at = now()
+		WHERE id = $1
+	`, id, disposal.Date, disposal.Proceeds, disposal.GainLoss, moveID, userID); err != nil {
+		return nil, fmt.Errorf("failed to dispose of asset: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit disposal: %w", err)
+	}
+	return r.FindAsset(ctx, orgID, id)
+}
+
+// Transfer moves an asset and records where it came from
+func (r *AssetsRepository) Transfer(ctx context.Context, orgID, userID, id uuid.UUID, request types.TransferRequest) (*types.Transfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	t := types.Transfer{AssetID: id, Date: request.Date, Note: request.Note, CreatedBy: &userID}
+	var state types.State
+	var departmentID, custodianID uuid.NullUUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT state, department_id, COALESCE(location, ''), custodian_id
+		FROM fixed_assets WHERE organization_id = $1 AND id = $2 FOR UPDATE
+	`, orgID, id).Scan(&state, &departmentID, &t.FromLocation, &custodianID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("asset %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock asset: %w", err)
+	}
+	if state == types.StateDisposed {
+		return nil, fmt.Errorf("%w: disposed assets cannot be transferred", ErrInvalidState)
+	}
+	if err := checkDepartment(ctx, tx, orgID, request.DepartmentID); err != nil {
+		return nil, err
+	}
+
+	t.FromDepartmentID = nullUUID(departmentID)
+	t.FromCustodianID = nullUUID(custodianID)
+	t.ToDepartmentID, t.ToLocation, t.ToCustodianID = t.FromDepartmentID, t.FromLocation, t.FromCustodianID
+	if request.DepartmentID != nil {
+		t.ToDepartmentID = request.DepartmentID
+	}
+	if request.Location != "" {
+		t.ToLocation = request.Location
+	}
+	if request.CustodianID != nil {
+		t.ToCustodianID = request.CustodianID
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO asset_transfers (
+			organization_id, asset_id, transfer_date, from_department_id, to_department_id, from_location,
+			to_location, from_custodian_id, to_custodian_id, note, created_by
+		) VALUES ($1, $2, $3::date, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, NULLIF($10, ''), $11)
+		RETURNING id, created_at
+	`, orgID, id, t.Date, t.FromDepartmentID, t.ToDepartmentID, t.FromLocation, t.ToLocation, t.FromCustodianID,
+		t.ToCustodianID, t.Note, userID).Scan(&t.ID, &t.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record transfer: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE fixed_assets SET department_id = $2, location = NULLIF($3, ''), custodian_id = $4, updated_by = $5,
+			updated_at = now()
+		WHERE id = $1
+	`, id, t.ToDepartmentID, t.ToLocation, t.ToCustodianID, userID); err != nil {
+		return nil, fmt.Errorf("failed to transfer asset: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transfer: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *AssetsRepository) ListTransfers(ctx context.Context, orgID, id uuid.UUID) ([]types.Transfer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, asset_id, transfer_date, from_department_id, to_department_id, COALESCE(from_location, ''),
+			COALESCE(to_location, ''), from_custodian_id, to_custodian_id, COALESCE(note, ''), created_by, created_at
+		FROM asset_transfers
+		WHERE organization_id = $1 AND asset_id = $2
+		ORDER BY transfer_date DESC, created_at DESC
+	`, orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []types.Transfer{}
+	for rows.Next() {
+		var t types.Transfer
+		var fromDepartment, toDepartment, fromCustodian, toCustodian, createdBy uuid.NullUUID
+		if err := rows.Scan(&t.ID, &t.AssetID, &t.Date, &fromDepartment, &toDepartment, &t.FromLocation,
+			&t.ToLocation, &fromCustodian, &toCustodian, &t.Note, &createdBy, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		t.FromDepartmentID = nullUUID(fromDepartment)
+		t.ToDepartmentID = nullUUID(toDepartment)
+		t.FromCustodianID = nullUUID(fromCustodian)
+		t.ToCustodianID = nullUUID(toCustodian)
+		t.CreatedBy = nullUUID(createdBy)
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// Register returns the confirmed assets held at some point of the period,
+// with the depreciation posted before and during it
+func (r *AssetsRepository) Register(ctx context.Context, filter types.RegisterFilter) ([]types.RegisterLine, error) {
+	where := []string{
+		"a.organization_id = $1", "a.state <> 'draft'", "a.acquisition_date <= $3::date",
+		"(a.disposal_date IS NULL OR a.disposal_date >= $2::date)",
+	}
+	args := []interface{}{filter.OrganizationID, filter.From, filter.To}
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		where = append(where, fmt.Sprintf("a.category_id = $%d", len(args)))
+	}
+	if filter.DepartmentID != nil {
+		args = append(args, *filter.DepartmentID)
+		where = append(where, fmt.Sprintf("a.department_id = $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, COALESCE(a.code, ''), a.name, a.category_id, c.name, a.department_id, a.state,
+			a.acquisition_date, a.disposal_date, a.cost,
+			COALESCE(SUM(d.amount) FILTER (WHERE d.period_date < $2::date), 0),
+			COALESCE(SUM(d.amount) FILTER (WHERE d.period_date >= $2::date), 0)
+		FROM fixed_assets a
+		JOIN asset_categories c ON c.id = a.category_id
+		LEFT JOIN asset_depreciation_lines d ON d.asset_id = a.id AND d.move_id IS NOT NULL AND d.period_date <= $3::date
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY a.id, c.name
+		ORDER BY c.name, a.acquisition_date, a.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build asset register: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []types.RegisterLine{}
+	for rows.Next() {
+		var l types.RegisterLine
+		var departmentID uuid.NullUUID
+		var disposalDate sql.NullTime
+		if err := rows.Scan(&l.AssetID, &l.Code, &l.Name, &l.CategoryID, &l.CategoryName, &departmentID, &l.State,
+			&l.AcquisitionDate, &disposalDate, &l.Cost, &l.OpeningAccumulated, &l.Depreciation); err != nil {
+			return nil, fmt.Errorf("failed to scan asset register line: %w", err)
+		}
+		l.DepartmentID = nullUUID(departmentID)
+		if disposalDate.Valid {
+			l.DisposalDate = &disposalDate.Time
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,625 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RunInterval is how often due depreciation is posted
+	RunInterval = time.Hour
+	// DefaultPageSize is the page size of the asset list
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of the asset list
+	MaxPageSize = 500
+	// MaxUsefulLifeMonths bounds useful lives to a century
+	MaxUsefulLifeMonths = 1200
+)
+
+// ErrInvalid wraps validation failures of categories, assets, disposals and transfers
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the assets service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// AssetsService keeps the fixed asset register: it acquires assets from
+// vendor bills, computes their depreciation schedules, posts the monthly
+// depreciation entries and handles disposals and transfers
+type AssetsService struct {
+	repo        repository.AssetsRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewAssetsService(repo repository.AssetsRepo, authService AuthService, logger *slog.Logger) *AssetsService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &AssetsService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// today is the current date at midnight UTC
+func (s *AssetsService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+// ListCategories returns the organization's asset categories
+func (s *AssetsService) ListCategories(ctx context.Context, orgID uuid.UUID) ([]types.Category, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListCategories(ctx, orgID)
+}
+
+// GetCategory returns an asset category
+func (s *AssetsService) GetCategory(ctx context.Context, orgID, id uuid.UUID) (*types.Category, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindCategory(ctx, orgID, id)
+}
+
+// CreateCategory validates and creates an asset category
+func (s *AssetsService) CreateCategory(ctx context.Context, orgID, userID uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCategory(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateCategory(ctx, orgID, userID, request)
+}
+
+// UpdateCategory validates and replaces an asset category
+func (s *AssetsService) UpdateCategory(ctx context.Context, orgID, userID, id uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCategory(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateCategory(ctx, orgID, userID, id, request)
+}
+
+// DeleteCategory removes an asset category without assets
+func (s *AssetsService) DeleteCategory(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteCategory(ctx, orgID, id)
+}
+
+func validateCategory(request *types.CategoryRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if request.Method == "" {
+		request.Method = types.MethodStraightLine
+	}
+	if err := validateDepreciation(request.Method, request.UsefulLifeMonths, request.DecliningRate); err != nil {
+		return err
+	}
+	if request.JournalID == uuid.Nil {
+		return fmt.Errorf("%w: journal_id is required", ErrInvalid)
+	}
+	if request.AssetAccountID == uuid.Nil || request.DepreciationAccountID == uuid.Nil || request.ExpenseAccountID == uuid.Nil {
+		return fmt.Errorf("%w: asset, depreciation and expense accounts are required", ErrInvalid)
+	}
+	if request.AssetAccountID == request.DepreciationAccountID || request.DepreciationAccountID == request.ExpenseAccountID {
+		return fmt.Errorf("%w: accumulated depreciation needs its own account", ErrInvalid)
+	}
+	return nil
+}
+
+func validateDepreciation(method types.Method, lifeMonths int, decliningRate float64) error {
+	if !method.IsValid() {
+		return fmt.Errorf("%w: unknown depreciation method %q", ErrInvalid, method)
+	}
+	if lifeMonths < 1 || lifeMonths > MaxUsefulLifeMonths {
+		return fmt.Errorf("%w: useful life must be between 1 and %d months", ErrInvalid, MaxUsefulLifeMonths)
+	}
+	if method == types.MethodDecliningBalance && (decliningRate <= 0 || decliningRate > 100) {
+		return fmt.Errorf("%w: declining balance needs a yearly rate above 0 and up to 100 percent", ErrInvalid)
+	}
+	return nil
+}
+
+// ListAssets returns a page of assets
+func (s *AssetsService) ListAssets(ctx context.Context, filter types.AssetFilter) ([]types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListAssets(ctx, filter)
+}
+
+// GetAsset returns an asset
+func (s *AssetsService) GetAsset(ctx context.Context, orgID, id uuid.UUID) (*types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindAsset(ctx, orgID, id)
+}
+
+// CreateAsset validates and creates a draft asset, from a vendor bill line
+// when one is given
+func (s *AssetsService) CreateAsset(ctx context.Context, orgID, userID uuid.UUID, request types.AssetRequest) (*types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	asset, err := s.buildAsset(ctx, orgID, request)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateAsset(ctx, userID, *asset)
+}
+
+// UpdateAsset validates and replaces a draft asset
+func (s *AssetsService) UpdateAsset(ctx context.Context, orgID, userID, id uuid.UUID, request types.AssetRequest) (*types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	asset, err := s.buildAsset(ctx, orgID, request)
+	if err != nil {
+		return nil, err
+	}
+	asset.ID = id
+	return s.repo.UpdateAsset(ctx, userID, *asset)
+}
+
+// DeleteAsset removes a draft asset
+func (s *AssetsService) DeleteAsset(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteAsset(ctx, orgID, id)
+}
+
+// buildAsset fills an asset request's defaults from its bill line and
+// category, then validates it
+func (s *AssetsService) buildAsset(ctx context.Context, orgID uuid.UUID, request types.AssetRequest) (*types.Asset, error) {
+	category, err := s.repo.FindCategory(ctx, orgID, request.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if !category.Active {
+		return nil, fmt.Errorf("%w: asset category %s is archived", ErrInvalid, category.Name)
+	}
+
+	asset := &types.Asset{
+		OrganizationID:   orgID,
+		CategoryID:       category.ID,
+		Code:             strings.TrimSpace(request.Code),
+		Name:             strings.TrimSpace(request.Name),
+		VendorID:         request.VendorID,
+		Cost:             request.Cost,
+		SalvageValue:     request.SalvageValue,
+		Method:           request.Method,
+		UsefulLifeMonths: request.UsefulLifeMonths,
+		DecliningRate:    category.DecliningRate,
+		DepartmentID:     request.DepartmentID,
+		Location:         strings.TrimSpace(request.Location),
+		CustodianID:      request.CustodianID,
+		Notes:            strings.TrimSpace(request.Notes),
+		State:            types.StateDraft,
+	}
+	if request.AcquisitionDate != nil {
+		asset.AcquisitionDate = request.AcquisitionDate.UTC().Truncate(24 * time.Hour)
+	}
+	if request.BillLineID != nil {
+		line, err := s.repo.FindBillLine(ctx, orgID, *request.BillLineID)
+		if err != nil {
+			return nil, err
+		}
+		asset.BillID, asset.BillLineID = &line.BillID, &line.ID
+		if asset.VendorID == nil {
+			asset.VendorID = &line.VendorID
+		}
+		if asset.Name == "" {
+			asset.Name = strings.TrimSpace(line.Name)
+		}
+		if asset.Cost == 0 {
+			asset.Cost = line.Amount
+		}
+		if asset.AcquisitionDate.IsZero() {
+			asset.AcquisitionDate = line.InvoiceDate.UTC().Truncate(24 * time.Hour)
+		}
+	}
+	if asset.Method == "" {
+		asset.Method = category.Method
+	}
+	if asset.UsefulLifeMonths == 0 {
+		asset.UsefulLifeMonths = category.UsefulLifeMonths
+	}
+	if request.DecliningRate != nil {
+		asset.DecliningRate = *request.DecliningRate
+	}
+
+	if asset.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if asset.AcquisitionDate.IsZero() {
+		return nil, fmt.Errorf("%w: acquisition_date is required", ErrInvalid)
+	}
+	if asset.Cost <= 0 {
+		return nil, fmt.Errorf("%w: cost must be positive", ErrInvalid)
+	}
+	if asset.SalvageValue < 0 || asset.SalvageValue > asset.Cost {
+		return nil, fmt.Errorf("%w: salvage value must be between 0 and the cost", ErrInvalid)
+	}
+	if err := validateDepreciation(asset.Method, asset.UsefulLifeMonths, asset.DecliningRate); err != nil {
+		return nil, err
+	}
+
+	asset.DepreciationStart = defaultDepreciationStart(asset.AcquisitionDate)
+	if request.DepreciationStart != nil {
+		start := monthStart(request.DepreciationStart.UTC())
+		if start.Before(monthStart(asset.AcquisitionDate)) {
+			return nil, fmt.Errorf("%w: depreciation cannot start before the acquisition month", ErrInvalid)
+		}
+		asset.DepreciationStart = start
+	}
+	return asset, nil
+}
+
+// defaultDepreciationStart starts depreciation with the first full month
+// the asset is held: its acquisition month when acquired on the 1st, the
+// next month otherwise
+func defaultDepreciationStart(acquired time.Time) time.Time {
+	start := monthStart(acquired)
+	if acquired.Day() != 1 {
+		start = start.AddDate(0, 1, 0)
+	}
+	return start
+}
+
+// ConfirmAsset computes a draft asset's depreciation schedule and starts
+// running it
+func (s *AssetsService) ConfirmAsset(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	asset, err := s.repo.FindAsset(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.State != types.StateDraft {
+		return nil, fmt.Errorf("%w: asset is already confirmed", repository.ErrInvalidState)
+	}
+	return s.repo.Confirm(ctx, orgID, userID, id, schedule(*asset))
+}
+
+// GetSchedule returns an asset's depreciation schedule. Draft assets get
+// the schedule confirming them would store.
+func (s *AssetsService) GetSchedule(ctx context.Context, orgID, id uuid.UUID) ([]types.DepreciationLine, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	asset, err := s.repo.FindAsset(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.State == types.StateDraft {
+		return schedule(*asset), nil
+	}
+	return s.repo.Schedule(ctx, orgID, id)
+}
+
+// schedule depreciates an asset month by month, from its depreciation start
+// down to its salvage value at the end of its useful life. Lines are dated
+// at month end; the last line absorbs rounding.
+func schedule(asset types.Asset) []types.DepreciationLine {
+	lines := []types.DepreciationLine{}
+	depreciable := roundAmount(asset.Cost - asset.SalvageValue)
+	if depreciable <= 0 {
+		return lines
+	}
+
+	book, accumulated := asset.Cost, 0.0
+	months := asset.UsefulLifeMonths
+	for i := 0; i < months; i++ {
+		var amount float64
+		switch asset.Method {
+		case types.MethodDecliningBalance:
+			amount = roundAmount(book * asset.DecliningRate / 100 / 12)
+			// Switch to straight line over the remaining life once it depreciates more
+			if straight := roundAmount((book - asset.SalvageValue) / float64(months-i)); straight > amount {
+				amount = straight
+			}
+		default:
+			amount = roundAmount(depreciable / float64(months))
+		}
+		if remaining := roundAmount(book - asset.SalvageValue); i == months-1 || amount > remaining {
+			amount = remaining
+		}
+		if amount <= 0 {
+			break
+		}
+		book = roundAmount(book - amount)
+		accumulated = roundAmount(accumulated + amount)
+		lines = append(lines, types.DepreciationLine{
+			AssetID:     asset.ID,
+			Sequence:    i + 1,
+			PeriodDate:  monthStart(asset.DepreciationStart).AddDate(0, i+1, -1),
+			Amount:      amount,
+			Accumulated: accumulated,
+			BookValue:   book,
+		})
+	}
+	return lines
+}
+
+// RunDepreciation posts the organization's depreciation lines due up to
+// upTo, today when nil
+func (s *AssetsService) RunDepreciation(ctx context.Context, orgID, userID uuid.UUID, upTo *time.Time) (*types.RunResult, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	date := s.today()
+	if upTo != nil {
+		date = upTo.UTC().Truncate(24 * time.Hour)
+	}
+	return s.run(ctx, orgID, &userID, date, nil)
+}
+
+// RunAll posts due depreciation for every organization with running
+// assets. It is called by the scheduler, outside of any user session.
+func (s *AssetsService) RunAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		result, err := s.run(ctx, orgID, nil, s.today(), nil)
+		if err != nil {
+			failed++
+			s.logger.Error("Depreciation run failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if result.Posted > 0 {
+			s.logger.Info("Depreciation posted", "organization_id", orgID, "entries", result.Posted, "amount", result.Amount)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("depreciation failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *AssetsService) run(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, upTo time.Time, assetID *uuid.UUID) (*types.RunResult, error) {
+	due, err := s.repo.DueDepreciation(ctx, orgID, upTo, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.RunResult{Date: upTo, Entries: []uuid.UUID{}}
+	for _, d := range due {
+		moveID, err := s.repo.PostDepreciation(ctx, d, userID, s.now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		if moveID == nil {
+			continue
+		}
+		result.Posted++
+		result.Amount = roundAmount(result.Amount + d.Line.Amount)
+		result.Entries = append(result.Entries, *moveID)
+	}
+	return result, nil
+}
+
+// Dispose sells or scraps an asset. Depreciation due up to the disposal
+// date is posted first; the rest of the schedule is dropped.
+func (s *AssetsService) Dispose(ctx context.Context, orgID, userID, id uuid.UUID, request types.DisposalRequest) (*types.Asset, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.Date.IsZero() {
+		request.Date = s.today()
+	}
+	request.Date = request.Date.UTC().Truncate(24 * time.Hour)
+	if request.Date.After(s.today()) {
+		return nil, fmt.Errorf("%w: disposal date is in the future", ErrInvalid)
+	}
+	if request.Proceeds < 0 {
+		return nil, fmt.Errorf("%w: proceeds cannot be negative", ErrInvalid)
+	}
+	if request.Proceeds > 0 && request.ProceedsAccountID == nil {
+		return nil, fmt.Errorf("%w: proceeds_account_id is required with proceeds", ErrInvalid)
+	}
+
+	asset, err := s.repo.FindAsset(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.State != types.StateRunning && asset.State != types.StateFullyDepreciated {
+		return nil, fmt.Errorf("%w: only confirmed assets can be disposed of", repository.ErrInvalidState)
+	}
+	if request.Date.Before(asset.AcquisitionDate) {
+		return nil, fmt.Errorf("%w: disposal date is before the acquisition", ErrInvalid)
+	}
+
+	if _, err := s.run(ctx, orgID, &userID, request.Date, &id); err != nil {
+		return nil, err
+	}
+	if asset, err = s.repo.FindAsset(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+	category, err := s.repo.FindCategory(ctx, orgID, asset.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	disposal, err := disposalEntry(*asset, *category, request)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Dispose(ctx, orgID, userID, id, *disposal)
+}
+
+// disposalEntry removes an asset from the books: it clears its cost and
+// accumulated depreciation, receives the proceeds, and books the difference
+// between proceeds and book value as a gain or a loss
+func disposalEntry(asset types.Asset, category types.Category, request types.DisposalRequest) (*types.Disposal, error) {
+	bookValue := roundAmount(asset.Cost - asset.Accumulated)
+	gainLoss := roundAmount(request.Proceeds - bookValue)
+
+	label := asset.Name
+	if asset.Code != "" {
+		label = asset.Code + " " + asset.Name
+	}
+	ref := "Disposal " + label
+	if request.Note != "" {
+		ref += ": " + strings.TrimSpace(request.Note)
+	}
+
+	var lines []types.EntryLine
+	if asset.Accumulated > 0 {
+		lines = append(lines, types.EntryLine{AccountID: category.DepreciationAccountID, Name: ref, Debit: roundAmount(asset.Accumulated)})
+	}
+	if request.Proceeds > 0 {
+		lines = append(lines, types.EntryLine{AccountID: *request.ProceedsAccountID, Name: ref, Debit: roundAmount(request.Proceeds)})
+	}
+	lines = append(lines, types.EntryLine{AccountID: category.AssetAccountID, Name: ref, Credit: roundAmount(asset.Cost)})
+	switch {
+	case gainLoss > 0:
+		if category.GainAccountID == nil {
+			return nil, fmt.Errorf("%w: asset category %s has no gain account", ErrInvalid, category.Name)
+		}
+		lines = append(lines, types.EntryLine{AccountID: *category.GainAccountID, Name: ref, Credit: gainLoss})
+	case gainLoss < 0:
+		if category.LossAccountID == nil {
+			return nil, fmt.Errorf("%w: asset category %s has no loss account", ErrInvalid, category.Name)
+		}
+		lines = append(lines, types.EntryLine{AccountID: *category.LossAccountID, Name: ref, Debit: -gainLoss})
+	}
+
+	return &types.Disposal{
+		Date:        request.Date,
+		Proceeds:    roundAmount(request.Proceeds),
+		GainLoss:    gainLoss,
+		JournalID:   category.JournalID,
+		Accumulated: asset.Accumulated,
+		Ref:         ref,
+		Lines:       lines,
+	}, nil
+}
+
+// Transfer moves an asset to another department, location or custodian
+func (s *AssetsService) Transfer(ctx context.Context, orgID, userID, id uuid.UUID, request types.TransferRequest) (*types.Transfer, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	request.Location = strings.TrimSpace(request.Location)
+	request.Note = strings.TrimSpace(request.Note)
+	if request.DepartmentID == nil && request.Location == "" && request.CustodianID == nil {
+		return nil, fmt.Errorf("%w: a department, location or custodian is required", ErrInvalid)
+	}
+	if request.Date.IsZero() {
+		request.Date = s.today()
+	}
+	request.Date = request.Date.UTC().Truncate(24 * time.Hour)
+	return s.repo.Transfer(ctx, orgID, userID, id, request)
+}
+
+// ListTransfers returns an asset's transfers, most recent first
+func (s *AssetsService) ListTransfers(ctx context.Context, orgID, id uuid.UUID) ([]types.Transfer, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindAsset(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTransfers(ctx, orgID, id)
+}
+
+// Register reports each asset's cost, depreciation and book value over a
+// period, by default the year to date
+func (s *AssetsService) Register(ctx context.Context, filter types.RegisterFilter) (*types.Register, error) {
+	if err := s.authService.CheckPermission(ctx, "assets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.To.IsZero() {
+		filter.To = s.today()
+	}
+	filter.To = filter.To.UTC().Truncate(24 * time.Hour)
+	if filter.From.IsZero() {
+		filter.From = time.Date(filter.To.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	filter.From = filter.From.UTC().Truncate(24 * time.Hour)
+	if filter.From.After(filter.To) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalid)
+	}
+
+	lines, err := s.repo.Register(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return buildRegister(filter, lines), nil
+}
+
+func buildRegister(filter types.RegisterFilter, lines []types.RegisterLine) *types.Register {
+	register := &types.Register{From: filter.From, To: filter.To, Lines: lines, Categories: []types.RegisterTotal{}}
+	byCategory := make(map[uuid.UUID]int)
+	for i := range register.Lines {
+		l := &register.Lines[i]
+		l.Accumulated = roundAmount(l.OpeningAccumulated + l.Depreciation)
+		if l.DisposalDate == nil || l.DisposalDate.After(filter.To) {
+			l.BookValue = roundAmount(l.Cost - l.Accumulated)
+		}
+
+		idx, ok := byCategory[l.CategoryID]
+		if !ok {
+			idx = len(register.Categories)
+			byCategory[l.CategoryID] = idx
+			register.Categories = append(register.Categories, types.RegisterTotal{CategoryID: l.CategoryID, CategoryName: l.CategoryName})
+		}
+		for _, total := range []*types.RegisterTotal{&register.Categories[idx], &register.Total} {
+			total.Assets++
+			total.Cost = roundAmount(total.Cost + l.Cost)
+			total.Depreciation = roundAmount(total.Depreciation + l.Depreciation)
+			total.Accumulated = roundAmount(total.Accumulated + l.Accumulated)
+			total.BookValue = roundAmount(total.BookValue + l.BookValue)
+		}
+	}
+	return register
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/assets/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAssetsRepo struct {
+	categories []types.Category
+	billLines  []types.BillLine
+	assets     []types.Asset
+	schedules  map[uuid.UUID][]types.DepreciationLine
+	disposal   *types.Disposal
+}
+
+func newFakeAssetsRepo() *fakeAssetsRepo {
+	return &fakeAssetsRepo{schedules: make(map[uuid.UUID][]types.DepreciationLine)}
+}
+
+func (f *fakeAssetsRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeAssetsRepo) ListCategories(ctx context.Context, orgID uuid.UUID) ([]types.Category, error) {
+	return f.categories, nil
+}
+
+func (f *fakeAssetsRepo) FindCategory(ctx context.Context, orgID, id uuid.UUID) (*types.Category, error) {
+	for i := range f.categories {
+		if f.categories[i].ID == id {
+			return &f.categories[i], nil
+		}
+	}
+	return nil, fmt.Errorf("asset category %w", repository.ErrNotFound)
+}
+
+func (f *fakeAssetsRepo) CreateCategory(ctx context.Context, orgID, userID uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	c := types.Category{ID: uuid.New(), Name: request.Name, Method: request.Method, UsefulLifeMonths: request.UsefulLifeMonths}
+	f.categories = append(f.categories, c)
+	return &c, nil
+}
+
+func (f *fakeAssetsRepo) UpdateCategory(ctx context.Context, orgID, userID, id uuid.UUID, request types.CategoryRequest) (*types.Category, error) {
+	return f.FindCategory(ctx, orgID, id)
+}
+
+func (f *fakeAssetsRepo) DeleteCategory(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeAssetsRepo) FindBillLine(ctx context.Context, orgID, id uuid.UUID) (*types.BillLine, error) {
+	for i := range f.billLines {
+		if f.billLines[i].ID == id {
+			return &f.billLines[i], nil
+		}
+	}
+	return nil, fmt.Errorf("posted vendor bill line %w", repository.ErrNotFound)
+}
+
+func (f *fakeAssetsRepo) ListAssets(ctx context.Context, filter types.AssetFilter) ([]types.Asset, error) {
+	return f.assets, nil
+}
+
+func (f *fakeAssetsRepo) FindAsset(ctx context.Context, orgID, id uuid.UUID) (*types.Asset, error) {
+	for i := range f.assets {
+		if f.assets[i].ID == id {
+			a := f.assets[i]
+			return &a, nil
+		}
+	}
+	return nil, fmt.Errorf("asset %w", repository.ErrNotFound)
+}
+
+func (f *fakeAssetsRepo) CreateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error) {
+	asset.ID = uuid.New()
+	f.assets = append(f.assets, asset)
+	return &asset, nil
+}
+
+func (f *fakeAssetsRepo) UpdateAsset(ctx context.Context, userID uuid.UUID, asset types.Asset) (*types.Asset, error) {
+	return &asset, nil
+}
+
+func (f *fakeAssetsRepo) DeleteAsset(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeAssetsRepo) Confirm(ctx context.Context, orgID, userID, id uuid.UUID, lines []types.DepreciationLine) (*types.Asset, error) {
+	for i := range f.assets {
+		if f.assets[i].ID == id {
+			f.assets[i].State = types.StateRunning
+			f.schedules[id] = lines
+			return &f.assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("asset %w", repository.ErrNotFound)
+}
+
+func (f *fakeAssetsRepo) Schedule(ctx context.Context, orgID, id uuid.UUID) ([]types.DepreciationLine, error) {
+	return f.schedules[id], nil
+}
+
+func (f *fakeAssetsRepo) DueDepreciation(ctx context.Context, orgID uuid.UUID, upTo time.Time, assetID *uuid.UUID) ([]types.DueDepreciation, error) {
+	var due []types.DueDepreciation
+	for id, lines := range f.schedules {
+		if assetID != nil && id != *assetID {
+			continue
+		}
+		for i, l := range lines {
+			if l.MoveID == nil && !l.PeriodDate.After(upTo) {
+				due = append(due, types.DueDepreciation{Line: l, OrganizationID: orgID, Last: i == len(lines)-1})
+			}
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeAssetsRepo) PostDepreciation(ctx context.Context, due types.DueDepreciation, userID *uuid.UUID, at time.Time) (*uuid.UUID, error) {
+	moveID := uuid.New()
+	lines := f.schedules[due.Line.AssetID]
+	for i := range lines {
+		if lines[i].Sequence == due.Line.Sequence {
+			lines[i].MoveID = &moveID
+		}
+	}
+	for i := range f.assets {
+		if f.assets[i].ID == due.Line.AssetID {
+			f.assets[i].Accumulated = roundAmount(f.assets[i].Accumulated + due.Line.Amount)
+		}
+	}
+	return &moveID, nil
+}
+
+func (f *fakeAssetsRepo) Dispose(ctx context.Context, orgID, userID, id uuid.UUID, disposal types.Disposal) (*types.Asset, error) {
+	f.disposal = &disposal
+	asset, err := f.FindAsset(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	asset.State = types.StateDisposed
+	return asset, nil
+}
+
+func (f *fakeAssetsRepo) Transfer(ctx context.Context, orgID, userID, id uuid.UUID, request types.TransferRequest) (*types.Transfer, error) {
+	return &types.Transfer{AssetID: id, Date: request.Date, ToLocation: request.Location}, nil
+}
+
+func (f *fakeAssetsRepo) ListTransfers(ctx context.Context, orgID, id uuid.UUID) ([]types.Transfer, error) {
+	return nil, nil
+}
+
+func (f *fakeAssetsRepo) Register(ctx context.Context, filter types.RegisterFilter) ([]types.RegisterLine, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeAssetsRepo) *AssetsService {
+	svc := NewAssetsService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testCategory() types.Category {
+	gainID, lossID := uuid.New(), uuid.New()
+	return types.Category{
+		ID: uuid.New(), Name: "Vehicles", Method: types.MethodStraightLine, UsefulLifeMonths: 36, Active: true,
+		JournalID: uuid.New(), AssetAccountID: uuid.New(), DepreciationAccountID: uuid.New(),
+		ExpenseAccountID: uuid.New(), GainAccountID: &gainID, LossAccountID: &lossID,
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Run("straight line absorbs rounding in the last line", func(t *testing.T) {
+		lines := schedule(types.Asset{
+			Cost: 1000, SalvageValue: 0, Method: types.MethodStraightLine, UsefulLifeMonths: 3, DepreciationStart: day(1, 1),
+		})
+		require.Len(t, lines, 3)
+		assert.Equal(t, 333.33, lines[0].Amount)
+		assert.Equal(t, day(1, 31), lines[0].PeriodDate)
+		assert.Equal(t, day(2, 28), lines[1].PeriodDate)
+		assert.Equal(t, 333.34, lines[2].Amount)
+		assert.Equal(t, 1000.0, lines[2].Accumulated)
+		assert.Equal(t, 0.0, lines[2].BookValue)
+	})
+
+	t.Run("stops at the salvage value", func(t *testing.T) {
+		lines := schedule(types.Asset{
+			Cost: 12000, SalvageValue: 2400, Method: types.MethodStraightLine, UsefulLifeMonths: 48, DepreciationStart: day(2, 1),
+		})
+		require.Len(t, lines, 48)
+		assert.Equal(t, 200.0, lines[0].Amount)
+		assert.Equal(t, time.Date(2029, 1, 31, 0, 0, 0, 0, time.UTC), lines[47].PeriodDate)
+		assert.Equal(t, 2400.0, lines[47].BookValue)
+	})
+
+	t.Run("declining balance switches to straight line", func(t *testing.T) {
+		lines := schedule(types.Asset{
+			Cost: 10000, SalvageValue: 1000, Method: types.MethodDecliningBalance, DecliningRate: 40,
+			UsefulLifeMonths: 24, DepreciationStart: day(1, 1),
+		})
+		require.Len(t, lines, 24)
+		// 40% a year of 10,000 is 333.33 a month, below straight line's 375
+		assert.Equal(t, 375.0, lines[0].Amount)
+		for i := 1; i < len(lines); i++ {
+			assert.LessOrEqual(t, lines[i].Amount, lines[i-1].Amount+0.01)
+		}
+		assert.Equal(t, 1000.0, lines[23].BookValue)
+
+		lines = schedule(types.Asset{
+			Cost: 10000, Method: types.MethodDecliningBalance, DecliningRate: 60,
+			UsefulLifeMonths: 60, DepreciationStart: day(1, 1),
+		})
+		require.Len(t, lines, 60)
+		assert.Equal(t, 500.0, lines[0].Amount)
+		assert.Equal(t, 475.0, lines[1].Amount)
+		assert.Equal(t, 0.0, lines[59].BookValue)
+	})
+
+	t.Run("nothing to depreciate", func(t *testing.T) {
+		assert.Empty(t, schedule(types.Asset{Cost: 500, SalvageValue: 500, Method: types.MethodStraightLine, UsefulLifeMonths: 12}))
+	})
+}
+
+func TestCreateAsset(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	category := testCategory()
+	bill := types.BillLine{ID: uuid.New(), BillID: uuid.New(), VendorID: uuid.New(), Name: "Delivery van",
+		InvoiceDate: day(3, 14), Amount: 36000}
+
+	t.Run("from a vendor bill line", func(t *testing.T) {
+		repo := newFakeAssetsRepo()
+		repo.categories = []types.Category{category}
+		repo.billLines = []types.BillLine{bill}
+
+		asset, err := newTestService(repo).CreateAsset(ctx, orgID, userID, types.AssetRequest{
+			CategoryID: category.ID, BillLineID: &bill.ID, SalvageValue: 6000,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Delivery van", asset.Name)
+		assert.Equal(t, 36000.0, asset.Cost)
+		assert.Equal(t, &bill.VendorID, asset.VendorID)
+		assert.Equal(t, &bill.BillID, asset.BillID)
+		assert.Equal(t, day(3, 14), asset.AcquisitionDate)
+		// Acquired mid-month, depreciation starts the next month
+		assert.Equal(t, day(4, 1), asset.DepreciationStart)
+		assert.Equal(t, types.MethodStraightLine, asset.Method)
+		assert.Equal(t, 36, asset.UsefulLifeMonths)
+		assert.Equal(t, types.StateDraft, asset.State)
+	})
+
+	acquired := day(1, 1)
+	invalid := map[string]types.AssetRequest{
+		"missing name":           {CategoryID: category.ID, Cost: 100, AcquisitionDate: &acquired},
+		"missing date":           {CategoryID: category.ID, Name: "Laptop", Cost: 100},
+		"no cost":                {CategoryID: category.ID, Name: "Laptop", AcquisitionDate: &acquired},
+		"salvage above cost":     {CategoryID: category.ID, Name: "Laptop", Cost: 100, SalvageValue: 150, AcquisitionDate: &acquired},
+		"declining without rate": {CategoryID: category.ID, Name: "Laptop", Cost: 100, AcquisitionDate: &acquired, Method: types.MethodDecliningBalance},
+		"starts too early": {CategoryID: category.ID, Name: "Laptop", Cost: 100, AcquisitionDate: &acquired,
+			DepreciationStart: func() *time.Time { d := acquired.AddDate(0, -1, 0); return &d }()},
+	}
+	for name, req := range invalid {
+		t.Run(name, func(t *testing.T) {
+			repo := newFakeAssetsRepo()
+			repo.categories = []types.Category{category}
+			_, err := newTestService(repo).CreateAsset(ctx, orgID, userID, req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+
+	t.Run("acquired on the 1st starts that month", func(t *testing.T) {
+		repo := newFakeAssetsRepo()
+		repo.categories = []types.Category{category}
+		asset, err := newTestService(repo).CreateAsset(ctx, orgID, userID, types.AssetRequest{
+			CategoryID: category.ID, Name: "Laptop", Cost: 1800, AcquisitionDate: &acquired,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, acquired, asset.DepreciationStart)
+	})
+}
+
+func TestValidateCategory(t *testing.T) {
+	valid := func() types.CategoryRequest {
+		return types.CategoryRequest{
+			Name: "Buildings", UsefulLifeMonths: 480, JournalID: uuid.New(),
+			AssetAccountID: uuid.New(), DepreciationAccountID: uuid.New(), ExpenseAccountID: uuid.New(),
+		}
+	}
+
+	req := valid()
+	require.NoError(t, validateCategory(&req))
+	assert.Equal(t, types.MethodStraightLine, req.Method)
+
+	invalid := map[string]func(*types.CategoryRequest){
+		"missing name":           func(r *types.CategoryRequest) { r.Name = "" },
+		"unknown method":         func(r *types.CategoryRequest) { r.Method = "sum_of_years" },
+		"no useful life":         func(r *types.CategoryRequest) { r.UsefulLifeMonths = 0 },
+		"declining without rate": func(r *types.CategoryRequest) { r.Method = types.MethodDecliningBalance },
+		"missing journal":        func(r *types.CategoryRequest) { r.JournalID = uuid.Nil },
+		"shared account":         func(r *types.CategoryRequest) { r.DepreciationAccountID = r.AssetAccountID },
+	}
+	for name, change := range invalid {
+		t.Run(name, func(t *testing.T) {
+			req := valid()
+			change(&req)
+			assert.ErrorIs(t, validateCategory(&req), ErrInvalid)
+		})
+	}
+}
+
+func TestConfirmAndRun(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	repo := newFakeAssetsRepo()
+	asset := types.Asset{ID: uuid.New(), Cost: 1200, Method: types.MethodStraightLine, UsefulLifeMonths: 12,
+		DepreciationStart: day(1, 1), State: types.StateDraft}
+	repo.assets = []types.Asset{asset}
+	svc := newTestService(repo)
+
+	confirmed, err := svc.ConfirmAsset(ctx, orgID, userID, asset.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.StateRunning, confirmed.State)
+	assert.Len(t, repo.schedules[asset.ID], 12)
+
+	_, err = svc.ConfirmAsset(ctx, orgID, userID, asset.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+
+	result, err := svc.RunDepreciation(ctx, orgID, userID, nil)
+	require.NoError(t, err)
+	// January to March are due on March 31
+	assert.Equal(t, 3, result.Posted)
+	assert.Equal(t, 300.0, result.Amount)
+
+	result, err = svc.RunDepreciation(ctx, orgID, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Posted)
+}
+
+func TestDisposalEntry(t *testing.T) {
+	category := testCategory()
+	proceedsAccount := uuid.New()
+	asset := types.Asset{Name: "Forklift", Code: "FA-7", Cost: 10000, Accumulated: 6000}
+
+	sumLines := func(lines []types.EntryLine) (debit, credit float64) {
+		for _, l := range lines {
+			debit += l.Debit
+			credit += l.Credit
+		}
+		return roundAmount(debit), roundAmount(credit)
+	}
+
+	t.Run("sold at a gain", func(t *testing.T) {
+		disposal, err := disposalEntry(asset, category, types.DisposalRequest{Date: day(3, 31), Proceeds: 5000, ProceedsAccountID: &proceedsAccount})
+		require.NoError(t, err)
+		assert.Equal(t, 1000.0, disposal.GainLoss)
+		assert.Equal(t, "Disposal FA-7 Forklift", disposal.Ref)
+		assert.Equal(t, []types.EntryLine{
+			{AccountID: category.DepreciationAccountID, Name: disposal.Ref, Debit: 6000},
+			{AccountID: proceedsAccount, Name: disposal.Ref, Debit: 5000},
+			{AccountID: category.AssetAccountID, Name: disposal.Ref, Credit: 10000},
+			{AccountID: *category.GainAccountID, Name: disposal.Ref, Credit: 1000},
+		}, disposal.Lines)
+		debit, credit := sumLines(disposal.Lines)
+		assert.Equal(t, debit, credit)
+	})
+
+	t.Run("scrapped at a loss", func(t *testing.T) {
+		disposal, err := disposalEntry(asset, category, types.DisposalRequest{Date: day(3, 31)})
+		require.NoError(t, err)
+		assert.Equal(t, -4000.0, disposal.GainLoss)
+		require.Len(t, disposal.Lines, 3)
+		assert.Equal(t, types.EntryLine{AccountID: *category.LossAccountID, Name: disposal.Ref, Debit: 4000}, disposal.Lines[2])
+		debit, credit := sumLines(disposal.Lines)
+		assert.Equal(t, debit, credit)
+	})
+
+	t.Run("needs the result account", func(t *testing.T) {
+		category := testCategory()
+		category.LossAccountID = nil
+		_, err := disposalEntry(asset, category, types.DisposalRequest{Date: day(3, 31)})
+		assert.ErrorIs(t, err, ErrInvalid)
+	})
+}
+
+func TestDisposePostsDueDepreciationFirst(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	category := testCategory()
+	repo := newFakeAssetsRepo()
+	asset := types.Asset{ID: uuid.New(), CategoryID: category.ID, Name: "Press", Cost: 1200,
+		Method: types.MethodStraightLine, UsefulLifeMonths: 12, DepreciationStart: day(1, 1), AcquisitionDate: day(1, 1),
+		State: types.StateRunning}
+	repo.categories = []types.Category{category}
+	repo.assets = []types.Asset{asset}
+	repo.schedules[asset.ID] = schedule(asset)
+	svc := newTestService(repo)
+
+	_, err := svc.Dispose(ctx, orgID, userID, asset.ID, types.DisposalRequest{Date: day(4, 30)})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.Dispose(ctx, orgID, userID, asset.ID, types.DisposalRequest{Date: day(2, 28), Proceeds: 100})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	disposed, err := svc.Dispose(ctx, orgID, userID, asset.ID, types.DisposalRequest{Date: day(2, 28)})
+	require.NoError(t, err)
+	assert.Equal(t, types.StateDisposed, disposed.State)
+	require.NotNil(t, repo.disposal)
+	assert.Equal(t, 200.0, repo.disposal.Accumulated)
+	assert.Equal(t, -1000.0, repo.disposal.GainLoss)
+	assert.Equal(t, category.JournalID, repo.disposal.JournalID)
+}
+
+func TestBuildRegister(t *testing.T) {
+	vehicles, buildings := uuid.New(), uuid.New()
+	disposed := day(2, 15)
+	register := buildRegister(types.RegisterFilter{From: day(1, 1), To: day(3, 31)}, []types.RegisterLine{
+		{CategoryID: vehicles, CategoryName: "Vehicles", Cost: 30000, OpeningAccumulated: 10000, Depreciation: 2500},
+		{CategoryID: vehicles, CategoryName: "Vehicles", Cost: 8000, OpeningAccumulated: 7000, Depreciation: 200, DisposalDate: &disposed},
+		{CategoryID: buildings, CategoryName: "Buildings", Cost: 500000, Depreciation: 3125},
+	})
+
+	assert.Equal(t, 12500.0, register.Lines[0].Accumulated)
+	assert.Equal(t, 17500.0, register.Lines[0].BookValue)
+	assert.Equal(t, 0.0, register.Lines[1].BookValue)
+	require.Len(t, register.Categories, 2)
+	assert.Equal(t, types.RegisterTotal{CategoryID: vehicles, CategoryName: "Vehicles", Assets: 2, Cost: 38000,
+		Depreciation: 2700, Accumulated: 19700, BookValue: 17500}, register.Categories[0])
+	assert.Equal(t, 3, register.Total.Assets)
+	assert.Equal(t, 514375.0, register.Total.BookValue)
+}
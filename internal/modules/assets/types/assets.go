@@ -0,0 +1,293 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Method is how an asset depreciates
+type Method string
+
+const (
+	// MethodStraightLine spreads the depreciable amount evenly over the useful life
+	MethodStraightLine Method = "straight_line"
+	// MethodDecliningBalance applies a yearly rate to the book value, switching
+	// to straight line once that depreciates more
+	MethodDecliningBalance Method = "declining_balance"
+)
+
+func (m Method) IsValid() bool {
+	return m == MethodStraightLine || m == MethodDecliningBalance
+}
+
+// State is where an asset is in its life
+type State string
+
+const (
+	StateDraft            State = "draft"
+	StateRunning          State = "running"
+	StateFullyDepreciated State = "fully_depreciated"
+	StateDisposed         State = "disposed"
+)
+
+func (s State) IsValid() bool {
+	switch s {
+	case StateDraft, StateRunning, StateFullyDepreciated, StateDisposed:
+		return true
+	}
+	return false
+}
+
+// Category sets the default depreciation of its assets and the accounts
+// their entries post to
+type Category struct {
+	ID                    uuid.UUID  `json:"id"`
+	OrganizationID        uuid.UUID  `json:"organization_id"`
+	Name                  string     `json:"name"`
+	Method                Method     `json:"method"`
+	UsefulLifeMonths      int        `json:"useful_life_months"`
+	DecliningRate         float64    `json:"declining_rate"`
+	JournalID             uuid.UUID  `json:"journal_id"`
+	AssetAccountID        uuid.UUID  `json:"asset_account_id"`
+	DepreciationAccountID uuid.UUID  `json:"depreciation_account_id"`
+	ExpenseAccountID      uuid.UUID  `json:"expense_account_id"`
+	GainAccountID         *uuid.UUID `json:"gain_account_id,omitempty"`
+	LossAccountID         *uuid.UUID `json:"loss_account_id,omitempty"`
+	Active                bool       `json:"active"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// CategoryRequest creates or replaces a category
+type CategoryRequest struct {
+	Name                  string     `json:"name"`
+	Method                Method     `json:"method"`
+	UsefulLifeMonths      int        `json:"useful_life_months"`
+	DecliningRate         float64    `json:"declining_rate,omitempty"`
+	JournalID             uuid.UUID  `json:"journal_id"`
+	AssetAccountID        uuid.UUID  `json:"asset_account_id"`
+	DepreciationAccountID uuid.UUID  `json:"depreciation_account_id"`
+	ExpenseAccountID      uuid.UUID  `json:"expense_account_id"`
+	GainAccountID         *uuid.UUID `json:"gain_account_id,omitempty"`
+	LossAccountID         *uuid.UUID `json:"loss_account_id,omitempty"`
+	Active                *bool      `json:"active,omitempty"`
+}
+
+// Asset is a fixed asset in the register
+type Asset struct {
+	ID                uuid.UUID  `json:"id"`
+	OrganizationID    uuid.UUID  `json:"organization_id"`
+	CategoryID        uuid.UUID  `json:"category_id"`
+	CategoryName      string     `json:"category_name"`
+	Code              string     `json:"code,omitempty"`
+	Name              string     `json:"name"`
+	VendorID          *uuid.UUID `json:"vendor_id,omitempty"`
+	BillID            *uuid.UUID `json:"bill_id,omitempty"`
+	BillLineID        *uuid.UUID `json:"bill_line_id,omitempty"`
+	AcquisitionDate   time.Time  `json:"acquisition_date"`
+	DepreciationStart time.Time  `json:"depreciation_start"`
+	Cost              float64    `json:"cost"`
+	SalvageValue      float64    `json:"salvage_value"`
+	Method            Method     `json:"method"`
+	UsefulLifeMonths  int        `json:"useful_life_months"`
+	DecliningRate     float64    `json:"declining_rate"`
+	DepartmentID      *uuid.UUID `json:"department_id,omitempty"`
+	Location          string     `json:"location,omitempty"`
+	CustodianID       *uuid.UUID `json:"custodian_id,omitempty"`
+	State             State      `json:"state"`
+	Accumulated       float64    `json:"accumulated_depreciation"`
+	BookValue         float64    `json:"book_value"`
+	DisposalDate      *time.Time `json:"disposal_date,omitempty"`
+	DisposalProceeds  *float64   `json:"disposal_proceeds,omitempty"`
+	DisposalGainLoss  *float64   `json:"disposal_gain_loss,omitempty"`
+	DisposalMoveID    *uuid.UUID `json:"disposal_move_id,omitempty"`
+	Notes             string     `json:"notes,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// AssetRequest creates or replaces a draft asset. With a bill line, the
+// vendor, acquisition date and cost default to the bill's. Method, useful
+// life and declining rate default to the category's.
+type AssetRequest struct {
+	CategoryID        uuid.UUID  `json:"category_id"`
+	Code              string     `json:"code,omitempty"`
+	Name              string     `json:"name"`
+	BillLineID        *uuid.UUID `json:"bill_line_id,omitempty"`
+	VendorID          *uuid.UUID `json:"vendor_id,omitempty"`
+	AcquisitionDate   *time.Time `json:"acquisition_date,omitempty"`
+	DepreciationStart *time.Time `json:"depreciation_start,omitempty"`
+	Cost              float64    `json:"cost,omitempty"`
+	SalvageValue      float64    `json:"salvage_value,omitempty"`
+	Method            Method     `json:"method,omitempty"`
+	UsefulLifeMonths  int        `json:"useful_life_months,omitempty"`
+	DecliningRate     *float64   `json:"declining_rate,omitempty"`
+	DepartmentID      *uuid.UUID `json:"department_id,omitempty"`
+	Location          string     `json:"location,omitempty"`
+	CustodianID       *uuid.UUID `json:"custodian_id,omitempty"`
+	Notes             string     `json:"notes,omitempty"`
+}
+
+// BillLine is a posted vendor bill line an asset can be acquired from
+type BillLine struct {
+	ID          uuid.UUID `json:"id"`
+	BillID      uuid.UUID `json:"bill_id"`
+	VendorID    uuid.UUID `json:"vendor_id"`
+	Name        string    `json:"name"`
+	InvoiceDate time.Time `json:"invoice_date"`
+	Amount      float64   `json:"amount"`
+}
+
+// AssetFilter narrows the asset list
+type AssetFilter struct {
+	OrganizationID uuid.UUID
+	CategoryID     *uuid.UUID
+	DepartmentID   *uuid.UUID
+	State          State
+	Search         string
+	Limit          int
+	Offset         int
+}
+
+// DepreciationLine is one month of an asset's depreciation schedule
+type DepreciationLine struct {
+	ID          uuid.UUID  `json:"id"`
+	AssetID     uuid.UUID  `json:"asset_id"`
+	Sequence    int        `json:"sequence"`
+	PeriodDate  time.Time  `json:"period_date"`
+	Amount      float64    `json:"amount"`
+	Accumulated float64    `json:"accumulated"`
+	BookValue   float64    `json:"book_value"`
+	MoveID      *uuid.UUID `json:"move_id,omitempty"`
+	PostedAt    *time.Time `json:"posted_at,omitempty"`
+}
+
+// DueDepreciation is a schedule line to post, with what its entry needs
+type DueDepreciation struct {
+	Line                  DepreciationLine
+	OrganizationID        uuid.UUID
+	AssetName             string
+	AssetCode             string
+	JournalID             uuid.UUID
+	DepreciationAccountID uuid.UUID
+	ExpenseAccountID      uuid.UUID
+	DepartmentAnalyticID  *uuid.UUID
+	// Last is set on the final line of the schedule
+	Last bool
+}
+
+// EntryLine is one line of a journal entry
+type EntryLine struct {
+	AccountID         uuid.UUID
+	Name              string
+	Debit             float64
+	Credit            float64
+	AnalyticAccountID *uuid.UUID
+}
+
+// DisposalRequest sells or scraps an asset
+type DisposalRequest struct {
+	Date time.Time `json:"date"`
+	// Proceeds of a sale; scrapped assets have none
+	Proceeds float64 `json:"proceeds,omitempty"`
+	// ProceedsAccountID receives the proceeds, typically a bank or receivable account
+	ProceedsAccountID *uuid.UUID `json:"proceeds_account_id,omitempty"`
+	Note              string     `json:"note,omitempty"`
+}
+
+// Disposal is what disposing of an asset records
+type Disposal struct {
+	Date      time.Time
+	Proceeds  float64
+	GainLoss  float64
+	JournalID uuid.UUID
+	// Accumulated is the accumulated depreciation the entry clears; the
+	// disposal fails if more depreciation was posted since
+	Accumulated float64
+	Ref         string
+	Lines       []EntryLine
+}
+
+// TransferRequest moves an asset to another department, location or
+// custodian. Fields left empty keep their current value.
+type TransferRequest struct {
+	Date         time.Time  `json:"date"`
+	DepartmentID *uuid.UUID `json:"department_id,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	CustodianID  *uuid.UUID `json:"custodian_id,omitempty"`
+	Note         string     `json:"note,omitempty"`
+}
+
+// Transfer records where an asset moved from and to
+type Transfer struct {
+	ID               uuid.UUID  `json:"id"`
+	AssetID          uuid.UUID  `json:"asset_id"`
+	Date             time.Time  `json:"date"`
+	FromDepartmentID *uuid.UUID `json:"from_department_id,omitempty"`
+	ToDepartmentID   *uuid.UUID `json:"to_department_id,omitempty"`
+	FromLocation     string     `json:"from_location,omitempty"`
+	ToLocation       string     `json:"to_location,omitempty"`
+	FromCustodianID  *uuid.UUID `json:"from_custodian_id,omitempty"`
+	ToCustodianID    *uuid.UUID `json:"to_custodian_id,omitempty"`
+	Note             string     `json:"note,omitempty"`
+	CreatedBy        *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// RunResult summarizes a depreciation run
+type RunResult struct {
+	Date    time.Time   `json:"date"`
+	Posted  int         `json:"posted"`
+	Amount  float64     `json:"amount"`
+	Entries []uuid.UUID `json:"entries"`
+}
+
+// RegisterFilter selects the assets and period of the register
+type RegisterFilter struct {
+	OrganizationID uuid.UUID
+	CategoryID     *uuid.UUID
+	DepartmentID   *uuid.UUID
+	From           time.Time
+	To             time.Time
+}
+
+// RegisterLine is an asset's cost and depreciation over the period
+type RegisterLine struct {
+	AssetID         uuid.UUID  `json:"asset_id"`
+	Code            string     `json:"code,omitempty"`
+	Name            string     `json:"name"`
+	CategoryID      uuid.UUID  `json:"category_id"`
+	CategoryName    string     `json:"category_name"`
+	DepartmentID    *uuid.UUID `json:"department_id,omitempty"`
+	State           State      `json:"state"`
+	AcquisitionDate time.Time  `json:"acquisition_date"`
+	DisposalDate    *time.Time `json:"disposal_date,omitempty"`
+	Cost            float64    `json:"cost"`
+	// OpeningAccumulated was posted before the period, Depreciation during it
+	OpeningAccumulated float64 `json:"opening_accumulated"`
+	Depreciation       float64 `json:"depreciation"`
+	Accumulated        float64 `json:"accumulated"`
+	// BookValue is zero once the asset is disposed
+	BookValue float64 `json:"book_value"`
+}
+
+// RegisterTotal totals the register lines of a category
+type RegisterTotal struct {
+	CategoryID   uuid.UUID `json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	Assets       int       `json:"assets"`
+	Cost         float64   `json:"cost"`
+	Depreciation float64   `json:"depreciation"`
+	Accumulated  float64   `json:"accumulated"`
+	BookValue    float64   `json:"book_value"`
+}
+
+// Register is the asset register report
+type Register struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Lines      []RegisterLine  `json:"lines"`
+	Categories []RegisterTotal `json:"categories"`
+	Total      RegisterTotal   `json:"total"`
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/auth/types"
@@ -49,6 +50,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	req.IPAddress = clientIP(r)
+	req.UserAgent = r.UserAgent()
 
 	response, err := h.service.LoginUser(r.Context(), req)
 	if err != nil {
@@ -61,6 +64,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from context (will be set by auth middleware)
 	userID, ok := r.Context().Value("userID").(uuid.UUID)
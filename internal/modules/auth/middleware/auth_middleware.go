@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/auth/utils"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
 
 	"github.com/google/uuid"
 )
@@ -50,14 +51,21 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Set user context
-		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
-		ctx = context.WithValue(ctx, "organizationID", claims.OrganizationID)
-		ctx = context.WithValue(ctx, "role", claims.Role)
-		ctx = context.WithValue(ctx, "isSuperAdmin", claims.IsSuperAdmin)
+		if claims.OrganizationID == uuid.Nil {
+			http.Error(w, "Invalid token: missing organization", http.StatusUnauthorized)
+			return
+		}
 
-		// Set organization ID in context for database operations
-		ctx = context.WithValue(ctx, "orgID", claims.OrganizationID)
+		// Resolve the request-scoped auth context once; handlers pass it to services
+		authCtx := &auth.AuthContext{
+			UserID:         claims.UserID,
+			OrganizationID: claims.OrganizationID,
+			CompanyID:      claims.CompanyID,
+			Roles:          []string{claims.Role},
+			IsSuperAdmin:   claims.IsSuperAdmin,
+			IsSandbox:      claims.IsSandbox,
+		}
+		ctx := auth.WithAuthContext(r.Context(), authCtx)
 
 		// Continue with the request
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -79,19 +87,36 @@ func isPublicRoute(path string) bool {
 		}
 	}
 
+	// Customer-facing endpoints such as status pages live under a dedicated prefix
+	publicPrefixes := []string{
+		"/public/",
+	}
+
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
 	return false
 }
 
 // GetUserIDFromContext extracts user ID from context
 func GetUserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
-	userID, ok := ctx.Value("userID").(uuid.UUID)
-	return userID, ok
+	authCtx, err := auth.FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return authCtx.UserID, true
 }
 
 // GetOrganizationIDFromContext extracts organization ID from context
 func GetOrganizationIDFromContext(ctx context.Context) (uuid.UUID, bool) {
-	orgID, ok := ctx.Value("organizationID").(uuid.UUID)
-	return orgID, ok
+	authCtx, err := auth.FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return authCtx.OrganizationID, true
 }
 
 // GetRoleFromContext extracts role from context
@@ -74,6 +74,14 @@ func (m *AuthModule) GetMiddleware() *middleware.AuthMiddleware {
 	return m.authMiddleware
 }
 
+// SetLoginRecorder records sign-in attempts for the audit trail. It must be
+// called after Init.
+func (m *AuthModule) SetLoginRecorder(recorder service.LoginRecorder) {
+	if m.authService != nil {
+		m.authService.SetLoginRecorder(recorder)
+	}
+}
+
 // GetAuthService returns the auth service for use by other modules
 func (m *AuthModule) GetAuthService() *service.AuthService {
 	return m.authService
@@ -191,6 +191,26 @@ func (r *authRepository) FindOrganizationByID(ctx context.Context, id uuid.UUID)
 	return &name, nil
 }
 
+func (r *authRepository) FindDefaultCompanyID(ctx context.Context, orgID uuid.UUID) (*uuid.UUID, error) {
+	query := `
+		SELECT id FROM companies
+		WHERE organization_id = $1 AND deleted_at IS NULL
+		ORDER BY is_default DESC NULLS LAST, created_at
+		LIMIT 1
+	`
+
+	var companyID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, orgID).Scan(&companyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find default company: %w", err)
+	}
+
+	return &companyID, nil
+}
+
 func (r *authRepository) CreateOrganizationUser(ctx context.Context, orgUser types.OrganizationUser) (*types.OrganizationUser, error) {
 	query := `
 		INSERT INTO organization_users
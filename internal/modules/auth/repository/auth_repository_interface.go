@@ -19,6 +19,9 @@ type AuthRepository interface {
 	// Organization operations
 	CreateOrganization(ctx context.Context, name string, createdBy uuid.UUID) (*uuid.UUID, error)
 	FindOrganizationByID(ctx context.Context, id uuid.UUID) (*string, error)
+	// FindDefaultCompanyID returns the organization's default company, or its
+	// oldest one when none is marked default; nil when it has no company
+	FindDefaultCompanyID(ctx context.Context, orgID uuid.UUID) (*uuid.UUID, error)
 
 	// Organization user operations
 	CreateOrganizationUser(ctx context.Context, orgUser types.OrganizationUser) (*types.OrganizationUser, error)
@@ -15,6 +15,7 @@ type MockAuthRepository struct {
 	users             map[uuid.UUID]types.User
 	usersByEmail      map[string]types.User
 	organizations     map[uuid.UUID]string
+	defaultCompanies  map[uuid.UUID]uuid.UUID
 	organizationUsers map[uuid.UUID][]types.OrganizationUser
 	passwordUpdates   map[uuid.UUID]string
 	errors            map[string]error
@@ -25,6 +26,7 @@ func NewMockAuthRepository() *MockAuthRepository {
 		users:             make(map[uuid.UUID]types.User),
 		usersByEmail:      make(map[string]types.User),
 		organizations:     make(map[uuid.UUID]string),
+		defaultCompanies:  make(map[uuid.UUID]uuid.UUID),
 		organizationUsers: make(map[uuid.UUID][]types.OrganizationUser),
 		passwordUpdates:   make(map[uuid.UUID]string),
 		errors:            make(map[string]error),
@@ -98,6 +100,17 @@ func (m *MockAuthRepository) FindOrganizationByID(ctx context.Context, id uuid.U
 	return nil, nil
 }
 
+func (m *MockAuthRepository) FindDefaultCompanyID(ctx context.Context, orgID uuid.UUID) (*uuid.UUID, error) {
+	if err, exists := m.errors["FindDefaultCompanyID"]; exists {
+		return nil, err
+	}
+
+	if companyID, exists := m.defaultCompanies[orgID]; exists {
+		return &companyID, nil
+	}
+	return nil, nil
+}
+
 func (m *MockAuthRepository) CreateOrganizationUser(ctx context.Context, orgUser types.OrganizationUser) (*types.OrganizationUser, error) {
 	if err, exists := m.errors["CreateOrganizationUser"]; exists {
 		return nil, err
@@ -157,6 +170,10 @@ func (m *MockAuthRepository) AddOrganization(orgID uuid.UUID, name string) {
 	m.organizations[orgID] = name
 }
 
+func (m *MockAuthRepository) SetDefaultCompany(orgID, companyID uuid.UUID) {
+	m.defaultCompanies[orgID] = companyID
+}
+
 func (m *MockAuthRepository) AddOrganizationUser(orgUser types.OrganizationUser) {
 	m.organizationUsers[orgUser.UserID] = append(m.organizationUsers[orgUser.UserID], orgUser)
 }
@@ -16,9 +16,15 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// LoginRecorder records sign-in attempts for the audit trail
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, orgID, userID uuid.UUID, succeeded bool, ipAddress, userAgent string)
+}
+
 type AuthService struct {
-	repo   repository.AuthRepository
-	logger *log.Logger
+	repo     repository.AuthRepository
+	recorder LoginRecorder
+	logger   *log.Logger
 }
 
 var (
@@ -32,6 +38,20 @@ func NewAuthService(repo repository.AuthRepository) *AuthService {
 	}
 }
 
+// SetLoginRecorder records sign-ins and failed sign-ins of known users
+func (s *AuthService) SetLoginRecorder(recorder LoginRecorder) {
+	s.recorder = recorder
+}
+
+// recordLogin records a sign-in attempt of a user under their first
+// organization, the one they sign in to
+func (s *AuthService) recordLogin(ctx context.Context, req types.LoginRequest, userID uuid.UUID, orgUsers []types.OrganizationUser, succeeded bool) {
+	if s.recorder == nil || len(orgUsers) == 0 {
+		return
+	}
+	s.recorder.RecordLogin(ctx, orgUsers[0].OrganizationID, userID, succeeded, req.IPAddress, req.UserAgent)
+}
+
 func (s *AuthService) RegisterUser(ctx context.Context, req types.RegisterRequest) (*types.UserProfile, error) {
 	// Validate email format
 	if !isValidEmail(req.Email) {
@@ -118,17 +138,18 @@ func (s *AuthService) LoginUser(ctx context.Context, req types.LoginRequest) (*t
 		return nil, errors.New("invalid credentials")
 	}
 
-	// Check password
-	if err := checkPassword(req.Password, user.EncryptedPassword); err != nil {
-		return nil, errors.New("invalid credentials")
-	}
-
 	// Get user's organizations
 	orgUsers, err := s.repo.FindOrganizationUsersByUserID(ctx, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user organizations: %w", err)
 	}
 
+	// Check password
+	if err := checkPassword(req.Password, user.EncryptedPassword); err != nil {
+		s.recordLogin(ctx, req, user.ID, orgUsers, false)
+		return nil, errors.New("invalid credentials")
+	}
+
 	if len(orgUsers) == 0 {
 		return nil, errors.New("user has no organization access")
 	}
@@ -146,9 +167,14 @@ func (s *AuthService) LoginUser(ctx context.Context, req types.LoginRequest) (*t
 		return nil, fmt.Errorf("failed to update last sign in: %w", err)
 	}
 
+	companyID, err := s.repo.FindDefaultCompanyID(ctx, orgUser.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find default company: %w", err)
+	}
+
 	// Generate JWT tokens
 	jwtService := utils.NewJWTService()
-	accessToken, err := jwtService.GenerateAccessToken(user.ID, orgUser.OrganizationID, orgUser.Role, user.IsSuperAdmin)
+	accessToken, err := jwtService.GenerateAccessToken(user.ID, orgUser.OrganizationID, companyID, orgUser.Role, user.IsSuperAdmin)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -161,6 +187,7 @@ func (s *AuthService) LoginUser(ctx context.Context, req types.LoginRequest) (*t
 	expiresIn := int(accessTokenExp.Seconds()) // Convert duration to seconds
 
 	s.logger.Printf("User logged in successfully: %s (organization: %s)", user.ID, orgUser.OrganizationID)
+	s.recordLogin(ctx, req, user.ID, orgUsers, true)
 
 	return &types.LoginResponse{
 		AccessToken:  accessToken,
@@ -6,6 +6,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/auth/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/auth/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/auth/utils"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -130,6 +131,29 @@ func TestAuthService_LoginUser(t *testing.T) {
 		assert.Equal(t, user.ID, response.User.ID)
 	})
 
+	t.Run("Login token carries the default company", func(t *testing.T) {
+		password := "password123"
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		user := repository.CreateTestUser()
+		user.Email = "company-login@example.com"
+		user.EncryptedPassword = string(hashedPassword)
+		mockRepo.AddUser(user)
+
+		orgID := uuid.New()
+		companyID := uuid.New()
+		mockRepo.AddOrganization(orgID, "Company Org")
+		mockRepo.SetDefaultCompany(orgID, companyID)
+		mockRepo.AddOrganizationUser(repository.CreateTestOrganizationUser(user.ID, orgID))
+
+		response, err := svc.LoginUser(ctx, types.LoginRequest{Email: user.Email, Password: password})
+		require.NoError(t, err)
+
+		claims, err := utils.NewJWTService().ValidateToken(response.AccessToken)
+		require.NoError(t, err)
+		require.NotNil(t, claims.CompanyID)
+		assert.Equal(t, companyID, *claims.CompanyID)
+	})
+
 	t.Run("Invalid credentials - wrong password", func(t *testing.T) {
 		user := repository.CreateTestUser()
 		user.Email = "wrong-pass@example.com"
@@ -57,6 +57,9 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// IPAddress and UserAgent identify the client for the audit trail
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // LoginResponse represents successful login response
@@ -72,7 +75,9 @@ type LoginResponse struct {
 type TokenClaims struct {
 	jwt.RegisteredClaims
 	UserID         uuid.UUID `json:"user_id"`
-	OrganizationID uuid.UUID `json:"organization_id"`
-	Role           string    `json:"role"`
-	IsSuperAdmin   bool      `json:"is_super_admin"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CompanyID      *uuid.UUID `json:"company_id,omitempty"`
+	Role           string     `json:"role"`
+	IsSuperAdmin   bool       `json:"is_super_admin"`
+	IsSandbox      bool       `json:"is_sandbox,omitempty"`
 }
@@ -22,8 +22,18 @@ var (
 // JWTService provides JWT token operations
 type JWTService struct{}
 
-// GenerateAccessToken generates a new JWT access token
-func (s *JWTService) GenerateAccessToken(userID, orgID uuid.UUID, role string, isSuperAdmin bool) (string, error) {
+// GenerateAccessToken generates a new JWT access token. The company, when
+// given, is the one requests act for unless they name another.
+func (s *JWTService) GenerateAccessToken(userID, orgID uuid.UUID, companyID *uuid.UUID, role string, isSuperAdmin bool) (string, error) {
+	return s.generateAccessToken(userID, orgID, companyID, role, isSuperAdmin, false)
+}
+
+// GenerateSandboxAccessToken generates an access token scoped to a sandbox organization
+func (s *JWTService) GenerateSandboxAccessToken(userID, sandboxOrgID uuid.UUID, role string, isSuperAdmin bool) (string, error) {
+	return s.generateAccessToken(userID, sandboxOrgID, nil, role, isSuperAdmin, true)
+}
+
+func (s *JWTService) generateAccessToken(userID, orgID uuid.UUID, companyID *uuid.UUID, role string, isSuperAdmin, isSandbox bool) (string, error) {
 	claims := types.TokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenExp)),
@@ -33,8 +43,10 @@ func (s *JWTService) GenerateAccessToken(userID, orgID uuid.UUID, role string, i
 		},
 		UserID:         userID,
 		OrganizationID: orgID,
+		CompanyID:      companyID,
 		Role:           role,
 		IsSuperAdmin:   isSuperAdmin,
+		IsSandbox:      isSandbox,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -124,6 +136,11 @@ func SetJWTSecretKey(key string) {
 	jwtSecretKey = []byte(key)
 }
 
+// AccessTokenTTL returns how long issued access tokens remain valid
+func AccessTokenTTL() time.Duration {
+	return accessTokenExp
+}
+
 // SetTokenExpirations allows setting token expiration times
 func SetTokenExpirations(accessExp, refreshExp time.Duration) {
 	accessTokenExp = accessExp
@@ -19,7 +19,7 @@ func TestJWTService(t *testing.T) {
 
 	t.Run("Generate and validate access token", func(t *testing.T) {
 		// Generate token
-		token, err := svc.GenerateAccessToken(userID, orgID, role, isSuperAdmin)
+		token, err := svc.GenerateAccessToken(userID, orgID, nil, role, isSuperAdmin)
 		require.NoError(t, err)
 		assert.NotEmpty(t, token)
 
@@ -35,6 +35,17 @@ func TestJWTService(t *testing.T) {
 		assert.Equal(t, "alieze-erp", claims.Issuer)
 	})
 
+	t.Run("Access token carries the company", func(t *testing.T) {
+		companyID := uuid.New()
+		token, err := svc.GenerateAccessToken(userID, orgID, &companyID, role, isSuperAdmin)
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		require.NotNil(t, claims.CompanyID)
+		assert.Equal(t, companyID, *claims.CompanyID)
+	})
+
 	t.Run("Generate and validate refresh token", func(t *testing.T) {
 		// Generate refresh token
 		refreshToken, err := svc.GenerateRefreshToken(userID)
@@ -79,7 +90,7 @@ func TestJWTService(t *testing.T) {
 
 		// Generate expired token
 		svc := NewJWTService()
-		expiredToken, err := svc.GenerateAccessToken(userID, orgID, role, isSuperAdmin)
+		expiredToken, err := svc.GenerateAccessToken(userID, orgID, nil, role, isSuperAdmin)
 		require.NoError(t, err)
 
 		// Validation should fail
@@ -102,7 +113,7 @@ func TestJWTService(t *testing.T) {
 
 		// Generate token with different issuer
 		svc := NewJWTService()
-		token, err := svc.GenerateAccessToken(userID, orgID, role, isSuperAdmin)
+		token, err := svc.GenerateAccessToken(userID, orgID, nil, role, isSuperAdmin)
 		require.NoError(t, err)
 
 		// Restore original issuer for validation
@@ -116,7 +127,7 @@ func TestJWTService(t *testing.T) {
 
 	t.Run("GetTokenClaims without validation", func(t *testing.T) {
 		svc := NewJWTService()
-		token, err := svc.GenerateAccessToken(userID, orgID, role, isSuperAdmin)
+		token, err := svc.GenerateAccessToken(userID, orgID, nil, role, isSuperAdmin)
 		require.NoError(t, err)
 
 		// Get claims without full validation
@@ -147,7 +158,7 @@ func TestJWTConfiguration(t *testing.T) {
 		orgID := uuid.New()
 
 		// Generate token with new secret
-		token, err := svc.GenerateAccessToken(userID, orgID, "admin", false)
+		token, err := svc.GenerateAccessToken(userID, orgID, nil, "admin", false)
 		require.NoError(t, err)
 
 		// Should validate with new secret
@@ -181,7 +192,7 @@ func TestJWTConfiguration(t *testing.T) {
 		orgID := uuid.New()
 
 		// Generate token with custom expiration
-		token, err := svc.GenerateAccessToken(userID, orgID, "admin", false)
+		token, err := svc.GenerateAccessToken(userID, orgID, nil, "admin", false)
 		require.NoError(t, err)
 
 		// Validate token
@@ -195,3 +206,24 @@ func TestJWTConfiguration(t *testing.T) {
 		assert.WithinDuration(t, now.Add(customAccessExp), expiresAt, time.Minute)
 	})
 }
+
+func TestGenerateSandboxAccessToken(t *testing.T) {
+	svc := NewJWTService()
+	userID := uuid.New()
+	sandboxOrgID := uuid.New()
+
+	token, err := svc.GenerateSandboxAccessToken(userID, sandboxOrgID, "admin", false)
+	require.NoError(t, err)
+
+	claims, err := svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, sandboxOrgID, claims.OrganizationID)
+	assert.True(t, claims.IsSandbox)
+
+	token, err = svc.GenerateAccessToken(userID, sandboxOrgID, nil, "admin", false)
+	require.NoError(t, err)
+
+	claims, err = svc.ValidateToken(token)
+	require.NoError(t, err)
+	assert.False(t, claims.IsSandbox)
+}
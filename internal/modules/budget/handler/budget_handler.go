@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// BudgetHandler handles budgets, budget-vs-actual reports and threshold
+// alerts
+type BudgetHandler struct {
+	service *service.BudgetService
+}
+
+func NewBudgetHandler(service *service.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+func (h *BudgetHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/budgets", h.ListBudgets)
+	router.POST("/api/budgets", h.CreateBudget)
+	router.GET("/api/budgets/:id", h.GetBudget)
+	router.PUT("/api/budgets/:id", h.UpdateBudget)
+	router.DELETE("/api/budgets/:id", h.DeleteBudget)
+	router.GET("/api/budgets/:id/report", h.BudgetReport)
+
+	router.GET("/api/budget-report", h.Report)
+
+	router.GET("/api/budget-alerts", h.ListAlerts)
+	router.POST("/api/budget-alerts", h.CheckAlerts)
+	router.POST("/api/budget-alerts/:id/acknowledge", h.AcknowledgeAlert)
+}
+
+// ListBudgets handles GET /api/budgets with optional account_id,
+// department_id, project_id, from, to and active filters
+func (h *BudgetHandler) ListBudgets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := budgetFilter(w, r, authCtx.OrganizationID)
+	if !ok {
+		return
+	}
+
+	budgets, err := h.service.ListBudgets(r.Context(), filter)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budgets)
+}
+
+// CreateBudget handles POST /api/budgets
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.BudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.CreateBudget(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, budget)
+}
+
+// GetBudget handles GET /api/budgets/:id
+func (h *BudgetHandler) GetBudget(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.GetBudget(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budget)
+}
+
+// UpdateBudget handles PUT /api/budgets/:id
+func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.BudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.UpdateBudget(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, budget)
+}
+
+// DeleteBudget handles DELETE /api/budgets/:id
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteBudget(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BudgetReport handles GET /api/budgets/:id/report with an optional
+// as_of date
+func (h *BudgetHandler) BudgetReport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+	asOf, ok := queryDate(w, r, "as_of")
+	if !ok {
+		return
+	}
+
+	report, err := h.service.BudgetReport(r.Context(), authCtx.OrganizationID, id, asOf)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Report handles GET /api/budget-report, comparing the budgets matching
+// the ListBudgets filters to their consumption up to an optional as_of date
+func (h *BudgetHandler) Report(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := budgetFilter(w, r, authCtx.OrganizationID)
+	if !ok {
+		return
+	}
+	asOf, ok := queryDate(w, r, "as_of")
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Report(r.Context(), filter, asOf)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ListAlerts handles GET /api/budget-alerts with optional budget_id, open,
+// limit and offset filters
+func (h *BudgetHandler) ListAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.AlertFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Open:           q.Get("open") == "true",
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("budget_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid budget_id", http.StatusBadRequest)
+			return
+		}
+		filter.BudgetID = &id
+	}
+
+	alerts, err := h.service.ListAlerts(r.Context(), filter)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+// CheckAlerts handles POST /api/budget-alerts, raising due alerts without
+// waiting for the scheduled check
+func (h *BudgetHandler) CheckAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CheckAlerts(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// AcknowledgeAlert handles POST /api/budget-alerts/:id/acknowledge
+func (h *BudgetHandler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.service.AcknowledgeAlert(r.Context(), authCtx.OrganizationID, id, authCtx.UserID)
+	if err != nil {
+		writeBudgetError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, alert)
+}
+
+// budgetFilter reads the budget filters shared by the list and the report
+func budgetFilter(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) (types.BudgetFilter, bool) {
+	q := r.URL.Query()
+	filter := types.BudgetFilter{OrganizationID: orgID, Period: q.Get("period"), ActiveOnly: q.Get("active") == "true"}
+	for name, dest := range map[string]**uuid.UUID{
+		"account_id": &filter.AccountID, "department_id": &filter.DepartmentID, "project_id": &filter.ProjectID,
+	} {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return filter, false
+			}
+			*dest = &id
+		}
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		t, ok := queryDate(w, r, name)
+		if !ok {
+			return filter, false
+		}
+		*dest = t
+	}
+	return filter, true
+}
+
+func queryDate(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+		return nil, false
+	}
+	return &t, true
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeBudgetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicateName):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeBudgetAlerts = "budget.alerts"
+
+// BudgetAlertJobHandler handles queued budget threshold checks
+type BudgetAlertJobHandler struct {
+	budgetService *service.BudgetService
+}
+
+func NewBudgetAlertJobHandler(budgetService *service.BudgetService) *BudgetAlertJobHandler {
+	return &BudgetAlertJobHandler{
+		budgetService: budgetService,
+	}
+}
+
+// Handle processes a budget threshold check job
+func (h *BudgetAlertJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.budgetService.CheckAll(ctx); err != nil {
+		return fmt.Errorf("failed to check budgets: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *BudgetAlertJobHandler) JobType() string {
+	return JobTypeBudgetAlerts
+}
+
+// Scheduler checks budgets on a fixed interval, so thresholds crossed by
+// new journal entries and purchase orders alert without anyone asking
+type Scheduler struct {
+	handler     *BudgetAlertJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *BudgetAlertJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start checks budgets every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeBudgetAlerts, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeBudgetAlerts, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled budget check failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule budget check", "error", err)
+	}
+}
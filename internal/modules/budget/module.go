@@ -0,0 +1,78 @@
+package budget
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// BudgetModule represents the budget tracking module
+type BudgetModule struct {
+	budgetService *service.BudgetService
+	budgetHandler *handler.BudgetHandler
+	scheduler     *jobs.Scheduler
+	logger        *slog.Logger
+}
+
+// NewBudgetModule creates a new budget module
+func NewBudgetModule() *BudgetModule {
+	return &BudgetModule{}
+}
+
+// Name returns the module name
+func (m *BudgetModule) Name() string {
+	return "budget"
+}
+
+// Init initializes the budget module and starts scheduled threshold checks
+func (m *BudgetModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "budget")
+	m.logger.Info("Initializing budget module")
+
+	// Create repositories
+	budgetRepo := repository.NewBudgetRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.budgetService = service.NewBudgetService(budgetRepo, authAdapter, deps.EventBus, m.logger)
+	m.budgetService.SetFiscalPeriods(fiscal.NewPeriods(fiscal.NewStore(deps.DB)))
+
+	// Create scheduled threshold checks
+	alertJobHandler := jobs.NewBudgetAlertJobHandler(m.budgetService)
+	m.scheduler = jobs.NewScheduler(alertJobHandler, service.CheckInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.budgetHandler = handler.NewBudgetHandler(m.budgetService)
+
+	m.logger.Info("Budget module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers budget module routes
+func (m *BudgetModule) RegisterRoutes(router interface{}) {
+	if m.budgetHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.budgetHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the budget module
+func (m *BudgetModule) RegisterEventHandlers(bus interface{}) {
+	// Budgets are checked on a schedule, reading journal and purchase lines directly
+}
+
+// Health checks the health of the budget module
+func (m *BudgetModule) Health() error {
+	return nil
+}
@@ -0,0 +1,462 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a budget, alert, account, department or project does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateName is returned when another budget already has the name
+	ErrDuplicateName = errors.New("budget name already used")
+)
+
+// BudgetRepo defines the interface for budget repository operations
+type BudgetRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListBudgets(ctx context.Context, filter types.BudgetFilter) ([]types.Budget, error)
+	FindBudget(ctx context.Context, orgID, id uuid.UUID) (*types.Budget, error)
+	CreateBudget(ctx context.Context, orgID, userID uuid.UUID, request types.BudgetRequest) (*types.Budget, error)
+	UpdateBudget(ctx context.Context, orgID, userID, id uuid.UUID, request types.BudgetRequest) (*types.Budget, error)
+	DeleteBudget(ctx context.Context, orgID, id uuid.UUID) error
+	Consumption(ctx context.Context, budget types.Budget, from, to time.Time) ([]types.Consumption, error)
+	CreateAlert(ctx context.Context, alert types.Alert) (*types.Alert, error)
+	ListAlerts(ctx context.Context, filter types.AlertFilter) ([]types.Alert, error)
+	AcknowledgeAlert(ctx context.Context, orgID, id, userID uuid.UUID, at time.Time) (*types.Alert, error)
+}
+
+// BudgetRepository stores budgets and their alerts, and reads actuals from
+// posted journal lines and commitments from confirmed purchase orders
+type BudgetRepository struct {
+	db *sql.DB
+}
+
+// Ensure BudgetRepository implements BudgetRepo interface
+var _ BudgetRepo = &BudgetRepository{}
+
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+// ListOrganizationIDs returns the organizations with an active budget
+func (r *BudgetRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT organization_id FROM budgets WHERE active`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const budgetColumns = `
+	b.id, b.organization_id, b.name, b.account_id, b.department_id, b.project_id,
+	COALESCE(d.analytic_account_id, p.analytic_account_id), b.period_start, b.period_end, b.amount,
+	b.include_commitments, b.thresholds, b.active, COALESCE(b.notes, ''), b.created_at, b.updated_at
+`
+
+const budgetFrom = `
+	FROM budgets b
+	LEFT JOIN departments d ON d.id = b.department_id
+	LEFT JOIN projects p ON p.id = b.project_id
+`
+
+func scanBudget(row interface{ Scan(...interface{}) error }) (*types.Budget, error) {
+	var b types.Budget
+	var accountID, departmentID, projectID, analyticID uuid.NullUUID
+	var thresholds pq.Int64Array
+	err := row.Scan(&b.ID, &b.OrganizationID, &b.Name, &accountID, &departmentID, &projectID, &analyticID,
+		&b.PeriodStart, &b.PeriodEnd, &b.Amount, &b.IncludeCommitments, &thresholds, &b.Active, &b.Notes,
+		&b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for dest, v := range map[**uuid.UUID]uuid.NullUUID{
+		&b.AccountID: accountID, &b.DepartmentID: departmentID, &b.ProjectID: projectID, &b.AnalyticAccountID: analyticID,
+	} {
+		if v.Valid {
+			id := v.UUID
+			*dest = &id
+		}
+	}
+	b.Thresholds = make([]int, len(thresholds))
+	for i, t := range thresholds {
+		b.Thresholds[i] = int(t)
+	}
+	return &b, nil
+}
+
+func (r *BudgetRepository) ListBudgets(ctx context.Context, filter types.BudgetFilter) ([]types.Budget, error) {
+	where := []string{"b.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.AccountID != nil {
+		add("b.account_id = $%d", *filter.AccountID)
+	}
+	if filter.DepartmentID != nil {
+		add("b.department_id = $%d", *filter.DepartmentID)
+	}
+	if filter.ProjectID != nil {
+		add("b.project_id = $%d", *filter.ProjectID)
+	}
+	if filter.From != nil {
+		add("b.period_end >= $%d::date", *filter.From)
+	}
+	if filter.To != nil {
+		add("b.period_start <= $%d::date", *filter.To)
+	}
+	if filter.ActiveOnly {
+		where = append(where, "b.active")
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+budgetColumns+budgetFrom+` WHERE `+strings.Join(where, " AND ")+
+		` ORDER BY b.period_start DESC, b.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []types.Budget{}
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+func (r *BudgetRepository) FindBudget(ctx context.Context, orgID, id uuid.UUID) (*types.Budget, error) {
+	b, err := scanBudget(r.db.QueryRowContext(ctx, `
+		SELECT `+budgetColumns+budgetFrom+` WHERE b.organization_id = $1 AND b.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("budget %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find budget: %w", err)
+	}
+	return b, nil
+}
+
+func (r *BudgetRepository) CreateBudget(ctx context.Context, orgID, userID uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	return r.saveBudget(ctx, orgID, userID, nil, request)
+}
+
+func (r *BudgetRepository) UpdateBudget(ctx context.Context, orgID, userID, id uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	return r.saveBudget(ctx, orgID, userID, &id, request)
+}
+
+// saveBudget inserts a budget, or replaces budget id, after checking its
+// account and linking its department or project to an analytic account
+func (r *BudgetRepository) saveBudget(ctx context.Context, orgID, userID uuid.UUID, id *uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if request.AccountID != nil {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM account_accounts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL)
+		`, *request.AccountID, orgID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check account: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("account %w", ErrNotFound)
+		}
+	}
+	if request.DepartmentID != nil {
+		if err := ensureAnalyticAccount(ctx, tx, orgID, "department", *request.DepartmentID); err != nil {
+			return nil, err
+		}
+	}
+	if request.ProjectID != nil {
+		if err := ensureAnalyticAccount(ctx, tx, orgID, "project", *request.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	thresholds := make(pq.Int64Array, len(request.Thresholds))
+	for i, t := range request.Thresholds {
+		thresholds[i] = int64(t)
+	}
+	includeCommitments := request.IncludeCommitments == nil || *request.IncludeCommitments
+	active := request.Active == nil || *request.Active
+
+	var budgetID uuid.UUID
+	if id == nil {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO budgets (
+				organization_id, name, account_id, department_id, project_id, period_start, period_end, amount,
+				include_commitments, thresholds, active, notes, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6::date, $7::date, $8, $9, $10, $11, NULLIF($12, ''), $13, $13)
+			RETURNING id
+		`, orgID, request.Name, request.AccountID, request.DepartmentID, request.ProjectID, request.PeriodStart,
+			request.PeriodEnd, request.Amount, includeCommitments, thresholds, active, request.Notes, userID).Scan(&budgetID)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			UPDATE budgets SET
+				name = $3, account_id = $4, department_id = $5, project_id = $6, period_start = $7::date,
+				period_end = $8::date, amount = $9, include_commitments = $10, thresholds = $11, active = $12,
+				notes = NULLIF($13, ''), updated_by = $14, updated_at = now()
+			WHERE organization_id = $1 AND id = $2
+			RETURNING id
+		`, orgID, *id, request.Name, request.AccountID, request.DepartmentID, request.ProjectID, request.PeriodStart,
+			request.PeriodEnd, request.Amount, includeCommitments, thresholds, active, request.Notes, userID).Scan(&budgetID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("budget %w", ErrNotFound)
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateName, request.Name)
+		}
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget: %w", err)
+	}
+	return r.FindBudget(ctx, orgID, budgetID)
+}
+
+// ensureAnalyticAccount gives a department or project an analytic account
+// named after it, unless it already has one
+func ensureAnalyticAccount(ctx context.Context, tx *sql.Tx, orgID uuid.UUID, kind string, id uuid.UUID) error {
+	table := "departments"
+	if kind == "project" {
+		table = "projects"
+	}
+
+	var name string
+	var analyticID uuid.NullUUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT name, analytic_account_id FROM `+table+` WHERE id = $1 AND organization_id = $2 FOR UPDATE
+	`, id, orgID).Scan(&name, &analyticID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s %w", kind, ErrNotFound)
+		}
+		return fmt.Errorf("failed to find %s: %w", kind, err)
+	}
+	if analyticID.Valid {
+		return nil
+	}
+
+	var newID uuid.UUID
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO analytic_accounts (organization_id, name) VALUES ($1, $2) RETURNING id
+	`, orgID, name).Scan(&newID); err != nil {
+		return fmt.Errorf("failed to create analytic account: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET analytic_account_id = $1 WHERE id = $2`, newID, id); err != nil {
+		return fmt.Errorf("failed to link analytic account: %w", err)
+	}
+	return nil
+}
+
+func (r *BudgetRepository) DeleteBudget(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM budgets WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("budget %w", ErrNotFound)
+	}
+	return nil
+}
+
+// Consumption returns a budget's actuals and commitments per month between
+// from and to. Months without any are left out.
+func (r *BudgetRepository) Consumption(ctx context.Context, budget types.Budget, from, to time.Time) ([]types.Consumption, error) {
+	if (budget.DepartmentID != nil || budget.ProjectID != nil) && budget.AnalyticAccountID == nil {
+		// The department or project lost its analytic account, so nothing can be attributed to it
+		return nil, nil
+	}
+
+	actualWhere := []string{
+		"m.organization_id = $1", "m.state = 'posted'", "m.deleted_at IS NULL", "l.deleted_at IS NULL",
+		"m.date BETWEEN $2::date AND $3::date",
+	}
+	args := []interface{}{budget.OrganizationID, from, to}
+	if budget.AccountID != nil {
+		args = append(args, *budget.AccountID)
+		actualWhere = append(actualWhere, fmt.Sprintf("l.account_id = $%d", len(args)))
+	} else {
+		actualWhere = append(actualWhere, "a.account_type IN ('expense', 'depreciation')")
+	}
+	committed := `SELECT NULL::date, 0::numeric WHERE false`
+	if budget.AnalyticAccountID != nil {
+		args = append(args, *budget.AnalyticAccountID)
+		actualWhere = append(actualWhere, fmt.Sprintf("l.analytic_account_id = $%d", len(args)))
+		if budget.AccountID == nil {
+			committed = fmt.Sprintf(`
+				SELECT date_trunc('month', o.date_order)::date,
+					pl.price_subtotal * GREATEST(pl.product_qty - COALESCE(pl.qty_invoiced, 0), 0) / NULLIF(pl.product_qty, 0)
+				FROM purchase_order_lines pl
+				JOIN purchase_orders o ON o.id = pl.order_id
+				WHERE o.organization_id = $1 AND o.state IN ('purchase', 'done') AND o.deleted_at IS NULL
+					AND pl.deleted_at IS NULL AND pl.display_type IS NULL
+					AND o.date_order::date BETWEEN $2::date AND $3::date AND pl.account_analytic_id = $%d`, len(args))
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH actual AS (
+			SELECT date_trunc('month', m.date)::date AS month, COALESCE(l.debit, 0) - COALESCE(l.credit, 0) AS amount
+			FROM invoice_lines l
+			JOIN invoices m ON m.id = l.move_id
+			LEFT JOIN account_accounts a ON a.id = l.account_id
+			WHERE `+strings.Join(actualWhere, " AND ")+`
+		), committed (month, amount) AS (`+committed+`
+		)
+		SELECT month, SUM(actual), SUM(committed) FROM (
+			SELECT month, amount AS actual, 0 AS committed FROM actual
+			UNION ALL
+			SELECT month, 0, COALESCE(amount, 0) FROM committed
+		) x
+		GROUP BY month ORDER BY month
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute budget consumption: %w", err)
+	}
+	defer rows.Close()
+
+	var months []types.Consumption
+	for rows.Next() {
+		var c types.Consumption
+		if err := rows.Scan(&c.From, &c.Actual, &c.Committed); err != nil {
+			return nil, fmt.Errorf("failed to scan budget consumption: %w", err)
+		}
+		c.To = c.From.AddDate(0, 1, -1)
+		months = append(months, c)
+	}
+	return months, rows.Err()
+}
+
+const alertColumns = `
+	al.id, al.organization_id, al.budget_id, b.name, al.threshold, al.budget_amount, al.actual, al.committed,
+	al.consumed_percent, al.triggered_at, al.acknowledged_by, al.acknowledged_at
+`
+
+func scanAlert(row interface{ Scan(...interface{}) error }) (*types.Alert, error) {
+	var a types.Alert
+	var acknowledgedBy uuid.NullUUID
+	var acknowledgedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.OrganizationID, &a.BudgetID, &a.BudgetName, &a.Threshold, &a.BudgetAmount,
+		&a.Actual, &a.Committed, &a.ConsumedPercent, &a.TriggeredAt, &acknowledgedBy, &acknowledgedAt)
+	if err != nil {
+		return nil, err
+	}
+	if acknowledgedBy.Valid {
+		a.AcknowledgedBy = &acknowledgedBy.UUID
+	}
+	if acknowledgedAt.Valid {
+		a.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	return &a, nil
+}
+
+// CreateAlert records an alert. It returns nil when the budget already
+// alerted at the threshold.
+func (r *BudgetRepository) CreateAlert(ctx context.Context, alert types.Alert) (*types.Alert, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO budget_alerts (
+			organization_id, budget_id, threshold, budget_amount, actual, committed, consumed_percent, triggered_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (budget_id, threshold) DO NOTHING
+		RETURNING id
+	`, alert.OrganizationID, alert.BudgetID, alert.Threshold, alert.BudgetAmount, alert.Actual, alert.Committed,
+		alert.ConsumedPercent, alert.TriggeredAt).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create budget alert: %w", err)
+	}
+	alert.ID = id
+	return &alert, nil
+}
+
+func (r *BudgetRepository) ListAlerts(ctx context.Context, filter types.AlertFilter) ([]types.Alert, error) {
+	where := []string{"al.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.BudgetID != nil {
+		args = append(args, *filter.BudgetID)
+		where = append(where, fmt.Sprintf("al.budget_id = $%d", len(args)))
+	}
+	if filter.Open {
+		where = append(where, "al.acknowledged_at IS NULL")
+	}
+
+	query := `SELECT ` + alertColumns + ` FROM budget_alerts al JOIN budgets b ON b.id = al.budget_id WHERE ` +
+		strings.Join(where, " AND ") + ` ORDER BY al.triggered_at DESC, al.threshold DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budget alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []types.Alert{}
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget alert: %w", err)
+		}
+		alerts = append(alerts, *a)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *BudgetRepository) AcknowledgeAlert(ctx context.Context, orgID, id, userID uuid.UUID, at time.Time) (*types.Alert, error) {
+	// Alerts acknowledged before keep their first acknowledgement
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE budget_alerts SET acknowledged_by = $3, acknowledged_at = $4
+		WHERE organization_id = $1 AND id = $2 AND acknowledged_at IS NULL
+	`, orgID, id, userID, at); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge budget alert: %w", err)
+	}
+
+	a, err := scanAlert(r.db.QueryRowContext(ctx, `
+		SELECT `+alertColumns+` FROM budget_alerts al JOIN budgets b ON b.id = al.budget_id
+		WHERE al.organization_id = $1 AND al.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("budget alert %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find budget alert: %w", err)
+	}
+	return a, nil
+}
@@ -0,0 +1,532 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CheckInterval is how often budgets are checked against their thresholds
+	CheckInterval = time.Hour
+	// DefaultPageSize is the page size of the alert list
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of the alert list
+	MaxPageSize = 500
+	// MaxThreshold bounds alert thresholds, in percent of the budget
+	MaxThreshold = 1000
+	// lateEntryDays is how long after its end a budget is still checked,
+	// so journal entries posted late can raise its alerts
+	lateEntryDays = 31
+	// EventThresholdCrossed is published for each new alert
+	EventThresholdCrossed = "budget.threshold_crossed"
+)
+
+// ErrInvalid wraps validation failures of budgets
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the budget service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// FiscalPeriods resolves fiscal period parameters in the organization's
+// fiscal calendar
+type FiscalPeriods interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error)
+}
+
+// BudgetService manages budgets, compares them to actuals and commitments
+// and raises alerts when consumption crosses their thresholds
+type BudgetService struct {
+	repo        repository.BudgetRepo
+	authService AuthService
+	eventBus    *events.Bus
+	periods     FiscalPeriods
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewBudgetService(repo repository.BudgetRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *BudgetService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &BudgetService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetFiscalPeriods enables budgets and reports over fiscal periods
+func (s *BudgetService) SetFiscalPeriods(periods FiscalPeriods) {
+	s.periods = periods
+}
+
+// resolvePeriod returns the fiscal period a parameter names
+func (s *BudgetService) resolvePeriod(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error) {
+	if s.periods == nil {
+		return fiscal.Period{}, fmt.Errorf("%w: fiscal periods are not available", ErrInvalid)
+	}
+	period, err := s.periods.Resolve(ctx, orgID, spec)
+	if errors.Is(err, fiscal.ErrInvalidPeriod) {
+		return fiscal.Period{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return period, err
+}
+
+// resolveFilter sets the dates of a filter given a fiscal period
+func (s *BudgetService) resolveFilter(ctx context.Context, filter *types.BudgetFilter) error {
+	if filter.Period == "" {
+		return nil
+	}
+	if filter.From != nil || filter.To != nil {
+		return fmt.Errorf("%w: period cannot be combined with from and to", ErrInvalid)
+	}
+	period, err := s.resolvePeriod(ctx, filter.OrganizationID, filter.Period)
+	if err != nil {
+		return err
+	}
+	filter.From, filter.To = &period.Start, &period.End
+	return nil
+}
+
+// resolveBudgetPeriod sets the period of a budget given by fiscal period
+func (s *BudgetService) resolveBudgetPeriod(ctx context.Context, orgID uuid.UUID, request *types.BudgetRequest) error {
+	if request.FiscalPeriod == "" {
+		return nil
+	}
+	if !request.PeriodStart.IsZero() || !request.PeriodEnd.IsZero() {
+		return fmt.Errorf("%w: fiscal_period cannot be combined with period_start and period_end", ErrInvalid)
+	}
+	period, err := s.resolvePeriod(ctx, orgID, request.FiscalPeriod)
+	if err != nil {
+		return err
+	}
+	request.PeriodStart, request.PeriodEnd = period.Start, period.End
+	return nil
+}
+
+// today is the current date at midnight UTC
+func (s *BudgetService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+// ListBudgets returns the budgets matching the filter
+func (s *BudgetService) ListBudgets(ctx context.Context, filter types.BudgetFilter) ([]types.Budget, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.resolveFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+	return s.repo.ListBudgets(ctx, filter)
+}
+
+// GetBudget returns a budget
+func (s *BudgetService) GetBudget(ctx context.Context, orgID, id uuid.UUID) (*types.Budget, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindBudget(ctx, orgID, id)
+}
+
+// CreateBudget validates and creates a budget
+func (s *BudgetService) CreateBudget(ctx context.Context, orgID, userID uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.resolveBudgetPeriod(ctx, orgID, &request); err != nil {
+		return nil, err
+	}
+	if err := validateBudget(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateBudget(ctx, orgID, userID, request)
+}
+
+// UpdateBudget validates and replaces a budget. Alerts already raised are
+// kept, so raising the amount does not alert again at the same threshold.
+func (s *BudgetService) UpdateBudget(ctx context.Context, orgID, userID, id uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.resolveBudgetPeriod(ctx, orgID, &request); err != nil {
+		return nil, err
+	}
+	if err := validateBudget(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateBudget(ctx, orgID, userID, id, request)
+}
+
+// DeleteBudget removes a budget and its alerts
+func (s *BudgetService) DeleteBudget(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "budgets:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteBudget(ctx, orgID, id)
+}
+
+// validateBudget normalizes a budget request and checks its scope, period,
+// amount and thresholds
+func validateBudget(request *types.BudgetRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Notes = strings.TrimSpace(request.Notes)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if request.AccountID == nil && request.DepartmentID == nil && request.ProjectID == nil {
+		return fmt.Errorf("%w: an account, department or project is required", ErrInvalid)
+	}
+	if request.DepartmentID != nil && request.ProjectID != nil {
+		return fmt.Errorf("%w: a budget covers a department or a project, not both", ErrInvalid)
+	}
+	if request.PeriodStart.IsZero() || request.PeriodEnd.IsZero() {
+		return fmt.Errorf("%w: period_start and period_end are required", ErrInvalid)
+	}
+	request.PeriodStart = request.PeriodStart.UTC().Truncate(24 * time.Hour)
+	request.PeriodEnd = request.PeriodEnd.UTC().Truncate(24 * time.Hour)
+	if request.PeriodEnd.Before(request.PeriodStart) {
+		return fmt.Errorf("%w: period_end is before period_start", ErrInvalid)
+	}
+	if request.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalid)
+	}
+
+	if len(request.Thresholds) == 0 {
+		request.Thresholds = append([]int(nil), types.DefaultThresholds...)
+	}
+	thresholds := append([]int(nil), request.Thresholds...)
+	sort.Ints(thresholds)
+	for i, t := range thresholds {
+		if t < 1 || t > MaxThreshold {
+			return fmt.Errorf("%w: thresholds must be between 1 and %d percent", ErrInvalid, MaxThreshold)
+		}
+		if i > 0 && t == thresholds[i-1] {
+			return fmt.Errorf("%w: threshold %d is listed twice", ErrInvalid, t)
+		}
+	}
+	request.Thresholds = thresholds
+	return nil
+}
+
+// Report compares the budgets matching the filter to their consumption up
+// to asOf, today when nil
+func (s *BudgetService) Report(ctx context.Context, filter types.BudgetFilter, asOf *time.Time) (*types.Report, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	date := s.today()
+	if asOf != nil {
+		date = asOf.UTC().Truncate(24 * time.Hour)
+	}
+	if err := s.resolveFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+	budgets, err := s.repo.ListBudgets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.Report{AsOf: date, Lines: make([]types.BudgetLine, 0, len(budgets))}
+	for _, b := range budgets {
+		months, err := s.consumption(ctx, b, date)
+		if err != nil {
+			return nil, err
+		}
+		line := budgetLine(b, months, date)
+		report.Lines = append(report.Lines, line)
+		report.Amount += b.Amount
+		report.Actual += line.Actual
+		report.Committed += line.Committed
+		report.Consumed += line.Consumed
+	}
+	report.Amount = roundAmount(report.Amount)
+	report.Actual = roundAmount(report.Actual)
+	report.Committed = roundAmount(report.Committed)
+	report.Consumed = roundAmount(report.Consumed)
+	report.Remaining = roundAmount(report.Amount - report.Consumed)
+	return report, nil
+}
+
+// BudgetReport compares a budget to its consumption month by month, up to
+// asOf, today when nil
+func (s *BudgetService) BudgetReport(ctx context.Context, orgID, id uuid.UUID, asOf *time.Time) (*types.BudgetReport, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	date := s.today()
+	if asOf != nil {
+		date = asOf.UTC().Truncate(24 * time.Hour)
+	}
+	b, err := s.repo.FindBudget(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	months, err := s.consumption(ctx, *b, date)
+	if err != nil {
+		return nil, err
+	}
+	return &types.BudgetReport{
+		BudgetLine: budgetLine(*b, months, date),
+		Periods:    periods(*b, months),
+	}, nil
+}
+
+// consumption reads a budget's monthly consumption from its start to asOf
+// or its end, whichever comes first
+func (s *BudgetService) consumption(ctx context.Context, b types.Budget, asOf time.Time) ([]types.Consumption, error) {
+	if asOf.Before(b.PeriodStart) {
+		return nil, nil
+	}
+	to := b.PeriodEnd
+	if asOf.Before(to) {
+		to = asOf
+	}
+	return s.repo.Consumption(ctx, b, b.PeriodStart, to)
+}
+
+// budgetLine totals a budget's consumption and compares it to the budget
+// and to the part of it planned by asOf
+func budgetLine(b types.Budget, months []types.Consumption, asOf time.Time) types.BudgetLine {
+	line := types.BudgetLine{Budget: b}
+	for _, m := range months {
+		line.Actual += m.Actual
+		line.Committed += m.Committed
+	}
+	line.Actual = roundAmount(line.Actual)
+	line.Committed = roundAmount(line.Committed)
+	line.Consumed = line.Actual
+	if b.IncludeCommitments {
+		line.Consumed = roundAmount(line.Actual + line.Committed)
+	}
+	line.Remaining = roundAmount(b.Amount - line.Consumed)
+	line.ConsumedPercent = percent(line.Consumed, b.Amount)
+	if !asOf.Before(b.PeriodStart) {
+		elapsed := daysBetween(b.PeriodStart, minDate(asOf, b.PeriodEnd))
+		line.Planned = roundAmount(b.Amount * float64(elapsed) / float64(daysBetween(b.PeriodStart, b.PeriodEnd)))
+	}
+	line.Variance = roundAmount(line.Consumed - line.Planned)
+
+	lowest := types.DefaultThresholds[0]
+	if len(b.Thresholds) > 0 {
+		lowest = b.Thresholds[0]
+	}
+	switch {
+	case line.Consumed > b.Amount:
+		line.Status = types.StatusOver
+	case line.ConsumedPercent >= float64(lowest):
+		line.Status = types.StatusAtRisk
+	default:
+		line.Status = types.StatusUnder
+	}
+	return line
+}
+
+// periods splits a budget into calendar months, each planned in proportion
+// to its days within the budget
+func periods(b types.Budget, months []types.Consumption) []types.Period {
+	byMonth := make(map[time.Time]types.Consumption, len(months))
+	for _, m := range months {
+		byMonth[m.From] = m
+	}
+
+	total := float64(daysBetween(b.PeriodStart, b.PeriodEnd))
+	var result []types.Period
+	var cumulativePlanned, cumulativeConsumed float64
+	// Planned amounts follow the cumulative plan, so rounding never drifts
+	// from the budget amount
+	for month := time.Date(b.PeriodStart.Year(), b.PeriodStart.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(b.PeriodEnd); month = month.AddDate(0, 1, 0) {
+		start := month
+		if start.Before(b.PeriodStart) {
+			start = b.PeriodStart
+		}
+		end := minDate(month.AddDate(0, 1, -1), b.PeriodEnd)
+
+		m := byMonth[month]
+		planned := roundAmount(b.Amount * float64(daysBetween(b.PeriodStart, end)) / total)
+		p := types.Period{
+			Start:     start,
+			End:       end,
+			Planned:   roundAmount(planned - cumulativePlanned),
+			Actual:    roundAmount(m.Actual),
+			Committed: roundAmount(m.Committed),
+		}
+		cumulativePlanned = planned
+		cumulativeConsumed += p.Actual
+		if b.IncludeCommitments {
+			cumulativeConsumed += p.Committed
+		}
+		p.CumulativePlanned = planned
+		p.CumulativeConsumed = roundAmount(cumulativeConsumed)
+		result = append(result, p)
+	}
+	return result
+}
+
+// CheckAlerts raises the alerts of the organization's budgets whose
+// consumption crossed a threshold since the last check
+func (s *BudgetService) CheckAlerts(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.checkAlerts(ctx, orgID)
+}
+
+// CheckAll checks the budgets of every organization. It is called by the
+// scheduler, outside of any user session.
+func (s *BudgetService) CheckAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		result, err := s.checkAlerts(ctx, orgID)
+		if err != nil {
+			failed++
+			s.logger.Error("Budget check failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if len(result.Alerts) > 0 {
+			s.logger.Info("Budget thresholds crossed", "organization_id", orgID, "alerts", len(result.Alerts))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("budget check failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *BudgetService) checkAlerts(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	today := s.today()
+	from := today.AddDate(0, 0, -lateEntryDays)
+	budgets, err := s.repo.ListBudgets(ctx, types.BudgetFilter{
+		OrganizationID: orgID,
+		From:           &from,
+		To:             &today,
+		ActiveOnly:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.CheckResult{Budgets: len(budgets), Alerts: []types.Alert{}}
+	for _, b := range budgets {
+		months, err := s.consumption(ctx, b, today)
+		if err != nil {
+			return nil, err
+		}
+		line := budgetLine(b, months, today)
+		for _, threshold := range b.Thresholds {
+			if line.ConsumedPercent < float64(threshold) {
+				break
+			}
+			alert, err := s.repo.CreateAlert(ctx, types.Alert{
+				OrganizationID:  orgID,
+				BudgetID:        b.ID,
+				BudgetName:      b.Name,
+				Threshold:       threshold,
+				BudgetAmount:    b.Amount,
+				Actual:          line.Actual,
+				Committed:       line.Committed,
+				ConsumedPercent: line.ConsumedPercent,
+				TriggeredAt:     s.now().UTC(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			if alert == nil {
+				// Already raised by an earlier check
+				continue
+			}
+			result.Alerts = append(result.Alerts, *alert)
+			s.publishEvent(ctx, EventThresholdCrossed, map[string]interface{}{
+				"organization_id":  orgID,
+				"alert_id":         alert.ID,
+				"budget_id":        b.ID,
+				"budget_name":      b.Name,
+				"threshold":        threshold,
+				"amount":           b.Amount,
+				"consumed":         line.Consumed,
+				"consumed_percent": line.ConsumedPercent,
+			})
+		}
+	}
+	return result, nil
+}
+
+// publishEvent publishes an event to the event bus if available
+func (s *BudgetService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish budget event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListAlerts returns a page of budget alerts, most recent first
+func (s *BudgetService) ListAlerts(ctx context.Context, filter types.AlertFilter) ([]types.Alert, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListAlerts(ctx, filter)
+}
+
+// AcknowledgeAlert marks an alert as seen
+func (s *BudgetService) AcknowledgeAlert(ctx context.Context, orgID, id, userID uuid.UUID) (*types.Alert, error) {
+	if err := s.authService.CheckPermission(ctx, "budgets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.AcknowledgeAlert(ctx, orgID, id, userID, s.now().UTC())
+}
+
+// daysBetween counts the days from start to end, both included
+func daysBetween(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+func minDate(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func percent(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return roundAmount(part / whole * 100)
+}
+
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
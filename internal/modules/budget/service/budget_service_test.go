@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/budget/types"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBudgetRepo struct {
+	budgets     []types.Budget
+	consumption map[uuid.UUID][]types.Consumption
+	alerts      []types.Alert
+	requested   []time.Time
+}
+
+func newFakeBudgetRepo() *fakeBudgetRepo {
+	return &fakeBudgetRepo{consumption: make(map[uuid.UUID][]types.Consumption)}
+}
+
+func (f *fakeBudgetRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeBudgetRepo) ListBudgets(ctx context.Context, filter types.BudgetFilter) ([]types.Budget, error) {
+	var budgets []types.Budget
+	for _, b := range f.budgets {
+		if filter.From != nil && b.PeriodEnd.Before(*filter.From) || filter.To != nil && b.PeriodStart.After(*filter.To) {
+			continue
+		}
+		if filter.ActiveOnly && !b.Active {
+			continue
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, nil
+}
+
+func (f *fakeBudgetRepo) FindBudget(ctx context.Context, orgID, id uuid.UUID) (*types.Budget, error) {
+	for i := range f.budgets {
+		if f.budgets[i].ID == id {
+			return &f.budgets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("budget %w", repository.ErrNotFound)
+}
+
+func (f *fakeBudgetRepo) CreateBudget(ctx context.Context, orgID, userID uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	b := types.Budget{ID: uuid.New(), Name: request.Name, PeriodStart: request.PeriodStart, PeriodEnd: request.PeriodEnd,
+		Amount: request.Amount, Thresholds: request.Thresholds}
+	f.budgets = append(f.budgets, b)
+	return &b, nil
+}
+
+func (f *fakeBudgetRepo) UpdateBudget(ctx context.Context, orgID, userID, id uuid.UUID, request types.BudgetRequest) (*types.Budget, error) {
+	return f.FindBudget(ctx, orgID, id)
+}
+
+func (f *fakeBudgetRepo) DeleteBudget(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+// Consumption returns the budget's months starting between from and to
+func (f *fakeBudgetRepo) Consumption(ctx context.Context, budget types.Budget, from, to time.Time) ([]types.Consumption, error) {
+	f.requested = append(f.requested, to)
+	var months []types.Consumption
+	for _, m := range f.consumption[budget.ID] {
+		if !m.From.After(to) {
+			months = append(months, m)
+		}
+	}
+	return months, nil
+}
+
+func (f *fakeBudgetRepo) CreateAlert(ctx context.Context, alert types.Alert) (*types.Alert, error) {
+	for _, a := range f.alerts {
+		if a.BudgetID == alert.BudgetID && a.Threshold == alert.Threshold {
+			return nil, nil
+		}
+	}
+	alert.ID = uuid.New()
+	f.alerts = append(f.alerts, alert)
+	return &alert, nil
+}
+
+func (f *fakeBudgetRepo) ListAlerts(ctx context.Context, filter types.AlertFilter) ([]types.Alert, error) {
+	return f.alerts, nil
+}
+
+func (f *fakeBudgetRepo) AcknowledgeAlert(ctx context.Context, orgID, id, userID uuid.UUID, at time.Time) (*types.Alert, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeBudgetRepo) *BudgetService {
+	svc := NewBudgetService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// quarterBudget is a 9,000 budget over the first quarter of 2025, 90 days
+func quarterBudget() types.Budget {
+	departmentID := uuid.New()
+	return types.Budget{
+		ID: uuid.New(), Name: "Marketing Q1", DepartmentID: &departmentID,
+		PeriodStart: day(1, 1), PeriodEnd: day(3, 31), Amount: 9000,
+		IncludeCommitments: true, Thresholds: []int{80, 100}, Active: true,
+	}
+}
+
+func TestCreateBudget(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	accountID, departmentID, projectID := uuid.New(), uuid.New(), uuid.New()
+	valid := func() types.BudgetRequest {
+		return types.BudgetRequest{
+			Name: " Travel ", AccountID: &accountID, DepartmentID: &departmentID,
+			PeriodStart: time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC), PeriodEnd: day(12, 31), Amount: 5000,
+		}
+	}
+
+	invalid := map[string]func(*types.BudgetRequest){
+		"missing name":           func(r *types.BudgetRequest) { r.Name = "" },
+		"no scope":               func(r *types.BudgetRequest) { r.AccountID, r.DepartmentID = nil, nil },
+		"department and project": func(r *types.BudgetRequest) { r.ProjectID = &projectID },
+		"missing period":         func(r *types.BudgetRequest) { r.PeriodEnd = time.Time{} },
+		"ends before it starts":  func(r *types.BudgetRequest) { r.PeriodEnd = day(1, 1).AddDate(0, 0, -1) },
+		"zero amount":            func(r *types.BudgetRequest) { r.Amount = 0 },
+		"threshold zero":         func(r *types.BudgetRequest) { r.Thresholds = []int{0, 100} },
+		"threshold too high":     func(r *types.BudgetRequest) { r.Thresholds = []int{MaxThreshold + 1} },
+		"duplicated threshold":   func(r *types.BudgetRequest) { r.Thresholds = []int{100, 50, 100} },
+	}
+	for name, change := range invalid {
+		t.Run(name, func(t *testing.T) {
+			req := valid()
+			change(&req)
+			_, err := newTestService(newFakeBudgetRepo()).CreateBudget(ctx, orgID, userID, req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+
+	t.Run("normalizes the request", func(t *testing.T) {
+		budget, err := newTestService(newFakeBudgetRepo()).CreateBudget(ctx, orgID, userID, valid())
+		require.NoError(t, err)
+		assert.Equal(t, "Travel", budget.Name)
+		assert.Equal(t, day(1, 1), budget.PeriodStart)
+		assert.Equal(t, types.DefaultThresholds, budget.Thresholds)
+
+		req := valid()
+		req.Thresholds = []int{120, 50, 90}
+		budget, err = newTestService(newFakeBudgetRepo()).CreateBudget(ctx, orgID, userID, req)
+		require.NoError(t, err)
+		assert.Equal(t, []int{50, 90, 120}, budget.Thresholds)
+	})
+}
+
+func TestBudgetLine(t *testing.T) {
+	b := quarterBudget()
+	months := []types.Consumption{
+		{From: day(1, 1), Actual: 2500, Committed: 0},
+		{From: day(2, 1), Actual: 2000, Committed: 1000},
+	}
+
+	// 45 of 90 days elapsed on February 14
+	line := budgetLine(b, months, day(2, 14))
+	assert.Equal(t, 4500.0, line.Actual)
+	assert.Equal(t, 1000.0, line.Committed)
+	assert.Equal(t, 5500.0, line.Consumed)
+	assert.Equal(t, 3500.0, line.Remaining)
+	assert.Equal(t, 61.11, line.ConsumedPercent)
+	assert.Equal(t, 4500.0, line.Planned)
+	assert.Equal(t, 1000.0, line.Variance)
+	assert.Equal(t, types.StatusUnder, line.Status)
+
+	b.IncludeCommitments = false
+	line = budgetLine(b, months, day(2, 14))
+	assert.Equal(t, 4500.0, line.Consumed)
+	assert.Equal(t, 0.0, line.Variance)
+
+	b.IncludeCommitments = true
+	months = append(months, types.Consumption{From: day(3, 1), Actual: 2000})
+	line = budgetLine(b, months, day(6, 30))
+	assert.Equal(t, 9000.0, line.Planned)
+	assert.Equal(t, types.StatusAtRisk, line.Status)
+
+	months = append(months, types.Consumption{From: day(3, 1), Actual: 1600})
+	assert.Equal(t, types.StatusOver, budgetLine(b, months, day(3, 31)).Status)
+
+	assert.Equal(t, 0.0, budgetLine(b, nil, day(12, 31).AddDate(-1, 0, 0)).Planned)
+}
+
+func TestBudgetReport(t *testing.T) {
+	repo := newFakeBudgetRepo()
+	b := quarterBudget()
+	// Starting mid-January, the budget spans 75 days
+	b.PeriodStart = day(1, 16)
+	b.Amount = 7500
+	repo.budgets = []types.Budget{b}
+	repo.consumption[b.ID] = []types.Consumption{
+		{From: day(1, 1), Actual: 1000},
+		{From: day(3, 1), Actual: 500, Committed: 250},
+	}
+
+	asOf := day(2, 20)
+	report, err := newTestService(repo).BudgetReport(context.Background(), uuid.New(), b.ID, &asOf)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{asOf}, repo.requested)
+	assert.Equal(t, 1000.0, report.Consumed)
+	require.Len(t, report.Periods, 3)
+
+	assert.Equal(t, types.Period{Start: day(1, 16), End: day(1, 31), Planned: 1600, Actual: 1000,
+		CumulativePlanned: 1600, CumulativeConsumed: 1000}, report.Periods[0])
+	assert.Equal(t, 2800.0, report.Periods[1].Planned)
+	assert.Equal(t, 4400.0, report.Periods[1].CumulativePlanned)
+	assert.Equal(t, day(3, 31), report.Periods[2].End)
+	assert.Equal(t, 3100.0, report.Periods[2].Planned)
+	assert.Equal(t, 7500.0, report.Periods[2].CumulativePlanned)
+	assert.Equal(t, 1000.0, report.Periods[2].CumulativeConsumed)
+}
+
+func TestReportTotals(t *testing.T) {
+	repo := newFakeBudgetRepo()
+	first, second := quarterBudget(), quarterBudget()
+	repo.budgets = []types.Budget{first, second}
+	repo.consumption[first.ID] = []types.Consumption{{From: day(1, 1), Actual: 3000, Committed: 500}}
+	repo.consumption[second.ID] = []types.Consumption{{From: day(2, 1), Actual: 9500}}
+
+	report, err := newTestService(repo).Report(context.Background(), types.BudgetFilter{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, day(3, 31), report.AsOf)
+	require.Len(t, report.Lines, 2)
+	assert.Equal(t, 18000.0, report.Amount)
+	assert.Equal(t, 12500.0, report.Actual)
+	assert.Equal(t, 500.0, report.Committed)
+	assert.Equal(t, 13000.0, report.Consumed)
+	assert.Equal(t, 5000.0, report.Remaining)
+	assert.Equal(t, types.StatusOver, report.Lines[1].Status)
+}
+
+// octoberYear is the calendar of fiscal years starting in October
+type octoberYear struct{}
+
+func (octoberYear) Find(ctx context.Context, orgID uuid.UUID) (*fiscal.Calendar, error) {
+	return &fiscal.Calendar{StartMonth: time.October, Pattern: fiscal.PatternMonthly}, nil
+}
+
+func TestFiscalPeriods(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeBudgetRepo()
+	svc := newTestService(repo)
+	svc.SetFiscalPeriods(fiscal.NewPeriods(octoberYear{}))
+	repo.budgets = []types.Budget{quarterBudget()}
+	departmentID := uuid.New()
+
+	// FY2025 starts in October 2024, so its Q2 is January through March
+	// 2025 and its Q3 April through June
+	budget, err := svc.CreateBudget(ctx, uuid.New(), uuid.New(), types.BudgetRequest{
+		Name: "Events", DepartmentID: &departmentID, FiscalPeriod: "FY2025-Q3", Amount: 3000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, day(4, 1), budget.PeriodStart)
+	assert.Equal(t, day(6, 30), budget.PeriodEnd)
+
+	budgets, err := svc.ListBudgets(ctx, types.BudgetFilter{Period: "FY2025-Q2"})
+	require.NoError(t, err)
+	require.Len(t, budgets, 1)
+	assert.Equal(t, "Marketing Q1", budgets[0].Name)
+
+	report, err := svc.Report(ctx, types.BudgetFilter{Period: "FY2025"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, report.Lines, 2)
+
+	_, err = svc.ListBudgets(ctx, types.BudgetFilter{Period: "2025-Q2"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	from := day(1, 1)
+	_, err = svc.ListBudgets(ctx, types.BudgetFilter{Period: "FY2025", From: &from})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestCheckAlerts(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakeBudgetRepo()
+
+	current := quarterBudget()
+	ended := quarterBudget()
+	ended.PeriodStart, ended.PeriodEnd = day(1, 1), day(1, 31)
+	stale := quarterBudget()
+	stale.PeriodStart, stale.PeriodEnd = day(1, 1).AddDate(-1, 0, 0), day(12, 31).AddDate(-1, 0, 0)
+	inactive := quarterBudget()
+	inactive.Active = false
+	repo.budgets = []types.Budget{current, ended, stale, inactive}
+	for _, b := range repo.budgets {
+		repo.consumption[b.ID] = []types.Consumption{{From: day(1, 1), Actual: 7000, Committed: 500}}
+	}
+
+	svc := newTestService(repo)
+	result, err := svc.CheckAlerts(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Budgets)
+	require.Len(t, result.Alerts, 1)
+	alert := result.Alerts[0]
+	assert.Equal(t, current.ID, alert.BudgetID)
+	assert.Equal(t, 80, alert.Threshold)
+	assert.Equal(t, 83.33, alert.ConsumedPercent)
+	assert.Equal(t, orgID, alert.OrganizationID)
+
+	// Each threshold alerts once
+	result, err = svc.CheckAlerts(ctx, orgID)
+	require.NoError(t, err)
+	assert.Empty(t, result.Alerts)
+
+	repo.consumption[current.ID] = append(repo.consumption[current.ID], types.Consumption{From: day(3, 1), Actual: 1600})
+	result, err = svc.CheckAlerts(ctx, orgID)
+	require.NoError(t, err)
+	require.Len(t, result.Alerts, 1)
+	assert.Equal(t, 100, result.Alerts[0].Threshold)
+	assert.Len(t, repo.alerts, 2)
+}
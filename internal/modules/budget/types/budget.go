@@ -0,0 +1,160 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status tells how a budget's consumption compares to its amount
+type Status string
+
+const (
+	StatusUnder Status = "under"
+	// StatusAtRisk budgets reached their lowest alert threshold
+	StatusAtRisk Status = "at_risk"
+	StatusOver   Status = "over"
+)
+
+// DefaultThresholds are the alert thresholds of budgets created without any
+var DefaultThresholds = []int{80, 100}
+
+// Budget caps spending over a period on an account, a department or a
+// project, or an account within a department or project
+type Budget struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	AccountID      *uuid.UUID `json:"account_id,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"`
+	// AnalyticAccountID is the analytic account of the department or
+	// project; journal and purchase lines tagged with it count
+	AnalyticAccountID  *uuid.UUID `json:"analytic_account_id,omitempty"`
+	PeriodStart        time.Time  `json:"period_start"`
+	PeriodEnd          time.Time  `json:"period_end"`
+	Amount             float64    `json:"amount"`
+	IncludeCommitments bool       `json:"include_commitments"`
+	Thresholds         []int      `json:"thresholds"`
+	Active             bool       `json:"active"`
+	Notes              string     `json:"notes,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// BudgetRequest creates or replaces a budget. A fiscal period, such as
+// FY2025 or FY2025-Q2, sets the period in the organization's fiscal
+// calendar instead of period_start and period_end.
+type BudgetRequest struct {
+	Name               string     `json:"name"`
+	AccountID          *uuid.UUID `json:"account_id,omitempty"`
+	DepartmentID       *uuid.UUID `json:"department_id,omitempty"`
+	ProjectID          *uuid.UUID `json:"project_id,omitempty"`
+	PeriodStart        time.Time  `json:"period_start"`
+	PeriodEnd          time.Time  `json:"period_end"`
+	FiscalPeriod       string     `json:"fiscal_period,omitempty"`
+	Amount             float64    `json:"amount"`
+	IncludeCommitments *bool      `json:"include_commitments,omitempty"`
+	Thresholds         []int      `json:"thresholds,omitempty"`
+	Active             *bool      `json:"active,omitempty"`
+	Notes              string     `json:"notes,omitempty"`
+}
+
+// BudgetFilter narrows the budget list
+type BudgetFilter struct {
+	OrganizationID uuid.UUID
+	AccountID      *uuid.UUID
+	DepartmentID   *uuid.UUID
+	ProjectID      *uuid.UUID
+	// From and To select budgets whose period overlaps them
+	From *time.Time
+	To   *time.Time
+	// Period is a fiscal period parameter, such as FY2025-Q2, setting From
+	// and To
+	Period     string
+	ActiveOnly bool
+}
+
+// Consumption is what a budget used, between From and To inclusive
+type Consumption struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Actual    float64   `json:"actual"`
+	Committed float64   `json:"committed"`
+}
+
+// BudgetLine compares a budget to its consumption
+type BudgetLine struct {
+	Budget    Budget  `json:"budget"`
+	Actual    float64 `json:"actual"`
+	Committed float64 `json:"committed"`
+	// Consumed is actual plus, when the budget includes them, commitments
+	Consumed        float64 `json:"consumed"`
+	Remaining       float64 `json:"remaining"`
+	ConsumedPercent float64 `json:"consumed_percent"`
+	// Variance is consumed minus the budget prorated to the days elapsed;
+	// positive variances spend ahead of plan
+	Planned  float64 `json:"planned"`
+	Variance float64 `json:"variance"`
+	Status   Status  `json:"status"`
+}
+
+// Report is the budget-vs-actual report
+type Report struct {
+	AsOf      time.Time    `json:"as_of"`
+	Lines     []BudgetLine `json:"lines"`
+	Amount    float64      `json:"amount"`
+	Actual    float64      `json:"actual"`
+	Committed float64      `json:"committed"`
+	Consumed  float64      `json:"consumed"`
+	Remaining float64      `json:"remaining"`
+}
+
+// Period is one month of a budget's consumption
+type Period struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Planned   float64   `json:"planned"`
+	Actual    float64   `json:"actual"`
+	Committed float64   `json:"committed"`
+	// CumulativePlanned and CumulativeConsumed run from the budget start
+	CumulativePlanned  float64 `json:"cumulative_planned"`
+	CumulativeConsumed float64 `json:"cumulative_consumed"`
+}
+
+// BudgetReport is a budget's consumption month by month
+type BudgetReport struct {
+	BudgetLine
+	Periods []Period `json:"periods"`
+}
+
+// Alert is raised when a budget's consumption first reaches a threshold
+type Alert struct {
+	ID              uuid.UUID  `json:"id"`
+	OrganizationID  uuid.UUID  `json:"organization_id"`
+	BudgetID        uuid.UUID  `json:"budget_id"`
+	BudgetName      string     `json:"budget_name"`
+	Threshold       int        `json:"threshold"`
+	BudgetAmount    float64    `json:"budget_amount"`
+	Actual          float64    `json:"actual"`
+	Committed       float64    `json:"committed"`
+	ConsumedPercent float64    `json:"consumed_percent"`
+	TriggeredAt     time.Time  `json:"triggered_at"`
+	AcknowledgedBy  *uuid.UUID `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// AlertFilter narrows the alert list
+type AlertFilter struct {
+	OrganizationID uuid.UUID
+	BudgetID       *uuid.UUID
+	Open           bool
+	Limit          int
+	Offset         int
+}
+
+// CheckResult summarizes a threshold check
+type CheckResult struct {
+	Budgets int     `json:"budgets"`
+	Alerts  []Alert `json:"alerts"`
+}
@@ -0,0 +1,599 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CalendarHandler handles HTTP requests for business calendars and holiday sets
+type CalendarHandler struct {
+	service *service.CalendarService
+}
+
+func NewCalendarHandler(service *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+func (h *CalendarHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/business-calendars", h.ListCalendars)
+	router.POST("/api/v1/business-calendars", h.CreateCalendar)
+	router.GET("/api/v1/business-calendars/:id", h.GetCalendar)
+	router.PUT("/api/v1/business-calendars/:id", h.UpdateCalendar)
+	router.DELETE("/api/v1/business-calendars/:id", h.DeleteCalendar)
+	router.GET("/api/v1/business-hours/effective", h.Effective)
+	router.GET("/api/v1/holiday-sets", h.ListHolidaySets)
+	router.POST("/api/v1/holiday-sets", h.CreateHolidaySet)
+	router.GET("/api/v1/holiday-sets/:id", h.GetHolidaySet)
+	router.PUT("/api/v1/holiday-sets/:id", h.UpdateHolidaySet)
+	router.DELETE("/api/v1/holiday-sets/:id", h.DeleteHolidaySet)
+	router.GET("/api/v1/calendar-events", h.ListEvents)
+	router.POST("/api/v1/calendar-events", h.CreateEvent)
+	router.DELETE("/api/v1/calendar-events/:id", h.CancelEvent)
+	router.GET("/api/v1/free-busy", h.FreeBusy)
+	router.GET("/api/v1/fiscal-calendar", h.GetFiscalCalendar)
+	router.PUT("/api/v1/fiscal-calendar", h.SetFiscalCalendar)
+	router.GET("/api/v1/fiscal-periods", h.FiscalYear)
+	router.GET("/api/v1/fiscal-periods/resolve", h.ResolveFiscalPeriod)
+	router.GET("/api/v1/working-hours", h.ListWorkingHours)
+	router.GET("/api/v1/working-hours/:user_id", h.GetWorkingHours)
+	router.PUT("/api/v1/working-hours/:user_id", h.SetWorkingHours)
+	router.DELETE("/api/v1/working-hours/:user_id", h.DeleteWorkingHours)
+}
+
+// ListCalendars handles GET /api/v1/business-calendars
+func (h *CalendarHandler) ListCalendars(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	calendars, err := h.service.ListCalendars(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, calendars)
+}
+
+// CreateCalendar handles POST /api/v1/business-calendars
+func (h *CalendarHandler) CreateCalendar(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.BusinessCalendarCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cal, err := h.service.CreateCalendar(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cal)
+}
+
+// GetCalendar handles GET /api/v1/business-calendars/:id
+func (h *CalendarHandler) GetCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	cal, err := h.service.GetCalendar(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cal)
+}
+
+// UpdateCalendar handles PUT /api/v1/business-calendars/:id
+func (h *CalendarHandler) UpdateCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.BusinessCalendarUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cal, err := h.service.UpdateCalendar(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cal)
+}
+
+// DeleteCalendar handles DELETE /api/v1/business-calendars/:id
+func (h *CalendarHandler) DeleteCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCalendar(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Effective handles GET /api/v1/business-hours/effective?team_id=
+func (h *CalendarHandler) Effective(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var teamID *uuid.UUID
+	if raw := r.URL.Query().Get("team_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamID = &id
+	}
+
+	effective, err := h.service.Effective(r.Context(), authCtx.OrganizationID, teamID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, effective)
+}
+
+// ListHolidaySets handles GET /api/v1/holiday-sets
+func (h *CalendarHandler) ListHolidaySets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	sets, err := h.service.ListHolidaySets(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sets)
+}
+
+// CreateHolidaySet handles POST /api/v1/holiday-sets
+func (h *CalendarHandler) CreateHolidaySet(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.HolidaySetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set, err := h.service.CreateHolidaySet(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, set)
+}
+
+// GetHolidaySet handles GET /api/v1/holiday-sets/:id
+func (h *CalendarHandler) GetHolidaySet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid holiday set ID", http.StatusBadRequest)
+		return
+	}
+
+	set, err := h.service.GetHolidaySet(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, set)
+}
+
+// UpdateHolidaySet handles PUT /api/v1/holiday-sets/:id
+func (h *CalendarHandler) UpdateHolidaySet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid holiday set ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.HolidaySetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set, err := h.service.UpdateHolidaySet(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, set)
+}
+
+// DeleteHolidaySet handles DELETE /api/v1/holiday-sets/:id
+func (h *CalendarHandler) DeleteHolidaySet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid holiday set ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteHolidaySet(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListEvents handles GET /api/v1/calendar-events?user_id=&from=&to=
+func (h *CalendarHandler) ListEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userIDs, from, to, err := parseRangeQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.service.ListEvents(r.Context(), authCtx.OrganizationID, userIDs, from, to)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+// CreateEvent handles POST /api/v1/calendar-events
+func (h *CalendarHandler) CreateEvent(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.UserID == uuid.Nil {
+		req.UserID = authCtx.UserID
+	}
+
+	event, err := h.service.CreateEvent(r.Context(), authCtx.OrganizationID, &authCtx.UserID, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, event)
+}
+
+// CancelEvent handles DELETE /api/v1/calendar-events/:id. The event is kept as cancelled.
+func (h *CalendarHandler) CancelEvent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.CancelEvent(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// FreeBusy handles GET /api/v1/free-busy?user_id=&from=&to=
+func (h *CalendarHandler) FreeBusy(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userIDs, from, to, err := parseRangeQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	freeBusy, err := h.service.FreeBusy(r.Context(), authCtx.OrganizationID, userIDs, from, to)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, freeBusy)
+}
+
+// parseRangeQuery reads repeated user_id parameters and the RFC 3339 from
+// and to parameters
+func parseRangeQuery(r *http.Request) ([]uuid.UUID, time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	var userIDs []uuid.UUID
+	for _, raw := range query["user_id"] {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, errors.New("Invalid user ID")
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, errors.New("Invalid from time")
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, errors.New("Invalid to time")
+	}
+
+	return userIDs, from, to, nil
+}
+
+// GetFiscalCalendar handles GET /api/v1/fiscal-calendar
+func (h *CalendarHandler) GetFiscalCalendar(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	cal, err := h.service.GetFiscalCalendar(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cal)
+}
+
+// SetFiscalCalendar handles PUT /api/v1/fiscal-calendar
+func (h *CalendarHandler) SetFiscalCalendar(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req fiscal.Calendar
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cal, err := h.service.SetFiscalCalendar(r.Context(), authCtx.OrganizationID, &authCtx.UserID, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cal)
+}
+
+// FiscalYear handles GET /api/v1/fiscal-periods with an optional fiscal
+// year, the current one by default
+func (h *CalendarHandler) FiscalYear(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var fy int
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid fiscal year", http.StatusBadRequest)
+			return
+		}
+		fy = n
+	}
+
+	year, err := h.service.FiscalYear(r.Context(), authCtx.OrganizationID, fy)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, year)
+}
+
+// ResolveFiscalPeriod handles GET /api/v1/fiscal-periods/resolve with a
+// period such as FY2025-Q2 or current-quarter
+func (h *CalendarHandler) ResolveFiscalPeriod(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := h.service.ResolveFiscalPeriod(r.Context(), authCtx.OrganizationID, r.URL.Query().Get("period"))
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, period)
+}
+
+// ListWorkingHours handles GET /api/v1/working-hours
+func (h *CalendarHandler) ListWorkingHours(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.ListWorkingHours(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetWorkingHours handles GET /api/v1/working-hours/:user_id
+func (h *CalendarHandler) GetWorkingHours(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	hours, err := h.service.GetWorkingHours(r.Context(), authCtx.OrganizationID, userID)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hours)
+}
+
+// SetWorkingHours handles PUT /api/v1/working-hours/:user_id
+func (h *CalendarHandler) SetWorkingHours(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UserWorkingHoursRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hours, err := h.service.SetWorkingHours(r.Context(), authCtx.OrganizationID, userID, req)
+	if err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hours)
+}
+
+// DeleteWorkingHours handles DELETE /api/v1/working-hours/:user_id
+func (h *CalendarHandler) DeleteWorkingHours(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteWorkingHours(r.Context(), authCtx.OrganizationID, userID); err != nil {
+		writeCalendarError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCalendarError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CalendarModule represents the business hours and holiday calendar module
+type CalendarModule struct {
+	calendarHandler *handler.CalendarHandler
+	logger          *slog.Logger
+}
+
+// NewCalendarModule creates a new calendar module
+func NewCalendarModule() *CalendarModule {
+	return &CalendarModule{}
+}
+
+// Name returns the module name
+func (m *CalendarModule) Name() string {
+	return "calendar"
+}
+
+// Init initializes the calendar module
+func (m *CalendarModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "calendar")
+	m.logger.Info("Initializing calendar module")
+
+	// Create repositories
+	calendarRepo := repository.NewCalendarRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	calendarService := service.NewCalendarService(calendarRepo, authAdapter, m.logger)
+	calendarService.SetFiscalCalendars(fiscal.NewStore(deps.DB))
+
+	// Create handlers
+	m.calendarHandler = handler.NewCalendarHandler(calendarService)
+
+	m.logger.Info("Calendar module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers calendar module routes
+func (m *CalendarModule) RegisterRoutes(router interface{}) {
+	if m.calendarHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.calendarHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the calendar module
+func (m *CalendarModule) RegisterEventHandlers(bus interface{}) {
+	// Calendars are read on demand; no events needed
+}
+
+// Health checks the health of the calendar module
+func (m *CalendarModule) Health() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const calendarEventColumns = `id, organization_id, user_id, title, description, location, starts_at, ends_at, status, res_model, res_id, created_at, updated_at, created_by`
+
+func scanCalendarEvent(row rowScanner) (*types.CalendarEvent, error) {
+	var event types.CalendarEvent
+	err := row.Scan(
+		&event.ID, &event.OrganizationID, &event.UserID, &event.Title, &event.Description, &event.Location,
+		&event.StartsAt, &event.EndsAt, &event.Status, &event.ResModel, &event.ResID,
+		&event.CreatedAt, &event.UpdatedAt, &event.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ListEvents returns the events of the users, cancelled ones included, that
+// overlap from..to, earliest first
+func (r *CalendarRepository) ListEvents(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) ([]types.CalendarEvent, error) {
+	query := `
+		SELECT ` + calendarEventColumns + `
+		FROM calendar_events
+		WHERE organization_id = $1 AND user_id = ANY($2) AND starts_at < $4 AND ends_at > $3
+		ORDER BY starts_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, pq.Array(userIDs), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []types.CalendarEvent{}
+	for rows.Next() {
+		event, err := scanCalendarEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during calendar event iteration: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *CalendarRepository) CreateEvent(ctx context.Context, event types.CalendarEvent) (*types.CalendarEvent, error) {
+	query := `
+		INSERT INTO calendar_events (id, organization_id, user_id, title, description, location, starts_at, ends_at, status, res_model, res_id, created_at, updated_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW(), $12)
+		RETURNING ` + calendarEventColumns
+
+	created, err := scanCalendarEvent(r.db.QueryRowContext(ctx, query,
+		event.ID, event.OrganizationID, event.UserID, event.Title, event.Description, event.Location,
+		event.StartsAt, event.EndsAt, event.Status, event.ResModel, event.ResID, event.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar event: %w", err)
+	}
+
+	return created, nil
+}
+
+// CancelEvent marks an event cancelled, freeing its time
+func (r *CalendarRepository) CancelEvent(ctx context.Context, orgID, id uuid.UUID) (*types.CalendarEvent, error) {
+	query := `
+		UPDATE calendar_events SET status = 'cancelled', updated_at = NOW()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING ` + calendarEventColumns
+
+	event, err := scanCalendarEvent(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("calendar event %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to cancel calendar event: %w", err)
+	}
+
+	return event, nil
+}
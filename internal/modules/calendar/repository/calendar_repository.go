@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when a calendar, holiday set, event or user's working hours do not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// CalendarRepo defines the interface for business calendar repository operations
+type CalendarRepo interface {
+	ListCalendars(ctx context.Context, orgID uuid.UUID) ([]types.BusinessCalendar, error)
+	FindCalendar(ctx context.Context, orgID, id uuid.UUID) (*types.BusinessCalendar, error)
+	FindEffectiveCalendar(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.BusinessCalendar, error)
+	SaveCalendar(ctx context.Context, cal types.BusinessCalendar) (*types.BusinessCalendar, error)
+	DeleteCalendar(ctx context.Context, orgID, id uuid.UUID) error
+	ListHolidaySets(ctx context.Context, orgID uuid.UUID) ([]types.HolidaySet, error)
+	FindHolidaySet(ctx context.Context, orgID, id uuid.UUID) (*types.HolidaySet, error)
+	SaveHolidaySet(ctx context.Context, set types.HolidaySet) (*types.HolidaySet, error)
+	DeleteHolidaySet(ctx context.Context, orgID, id uuid.UUID) error
+	ListEvents(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) ([]types.CalendarEvent, error)
+	CreateEvent(ctx context.Context, event types.CalendarEvent) (*types.CalendarEvent, error)
+	CancelEvent(ctx context.Context, orgID, id uuid.UUID) (*types.CalendarEvent, error)
+	ListWorkingHours(ctx context.Context, orgID uuid.UUID) ([]types.UserWorkingHours, error)
+	FindWorkingHours(ctx context.Context, orgID, userID uuid.UUID) (*types.UserWorkingHours, error)
+	SaveWorkingHours(ctx context.Context, hours types.UserWorkingHours) (*types.UserWorkingHours, error)
+	DeleteWorkingHours(ctx context.Context, orgID, userID uuid.UUID) error
+}
+
+// CalendarRepository persists business calendars, holiday sets, calendar
+// events and users' working hours
+type CalendarRepository struct {
+	db *sql.DB
+}
+
+// Ensure CalendarRepository implements CalendarRepo interface
+var _ CalendarRepo = &CalendarRepository{}
+
+func NewCalendarRepository(db *sql.DB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+const calendarColumns = `id, organization_id, team_id, name, timezone, weekly_hours, holiday_set_id, is_default, active, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCalendar(row rowScanner) (*types.BusinessCalendar, error) {
+	var cal types.BusinessCalendar
+	var hoursJSON []byte
+	err := row.Scan(
+		&cal.ID, &cal.OrganizationID, &cal.TeamID, &cal.Name, &cal.Timezone, &hoursJSON,
+		&cal.HolidaySetID, &cal.IsDefault, &cal.Active, &cal.CreatedAt, &cal.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(hoursJSON, &cal.WeeklyHours); err != nil {
+		return nil, fmt.Errorf("invalid weekly hours: %w", err)
+	}
+	return &cal, nil
+}
+
+func (r *CalendarRepository) ListCalendars(ctx context.Context, orgID uuid.UUID) ([]types.BusinessCalendar, error) {
+	query := `SELECT ` + calendarColumns + ` FROM business_calendars WHERE organization_id = $1 ORDER BY team_id NULLS FIRST, name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list business calendars: %w", err)
+	}
+	defer rows.Close()
+
+	var calendars []types.BusinessCalendar
+	for rows.Next() {
+		cal, err := scanCalendar(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan business calendar: %w", err)
+		}
+		calendars = append(calendars, *cal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during business calendar iteration: %w", err)
+	}
+
+	return calendars, nil
+}
+
+func (r *CalendarRepository) FindCalendar(ctx context.Context, orgID, id uuid.UUID) (*types.BusinessCalendar, error) {
+	query := `SELECT ` + calendarColumns + ` FROM business_calendars WHERE organization_id = $1 AND id = $2`
+
+	cal, err := scanCalendar(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("business calendar %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get business calendar: %w", err)
+	}
+
+	return cal, nil
+}
+
+// FindEffectiveCalendar returns the team's active calendar, falling back to the
+// organization default. It returns nil when neither exists.
+func (r *CalendarRepository) FindEffectiveCalendar(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.BusinessCalendar, error) {
+	query := `
+		SELECT ` + calendarColumns + `
+		FROM business_calendars
+		WHERE organization_id = $1
+			AND active = true
+			AND ((team_id IS NOT NULL AND team_id = $2) OR (team_id IS NULL AND is_default = true))
+		ORDER BY team_id IS NULL
+		LIMIT 1
+	`
+
+	cal, err := scanCalendar(r.db.QueryRowContext(ctx, query, orgID, teamID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get effective business calendar: %w", err)
+	}
+
+	return cal, nil
+}
+
+// SaveCalendar inserts or updates a calendar. Making an organization-wide
+// calendar the default clears the flag on the previous default.
+func (r *CalendarRepository) SaveCalendar(ctx context.Context, cal types.BusinessCalendar) (*types.BusinessCalendar, error) {
+	hoursJSON, err := json.Marshal(cal.WeeklyHours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal weekly hours: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if cal.IsDefault && cal.TeamID == nil {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE business_calendars SET is_default = false, updated_at = NOW()
+			WHERE organization_id = $1 AND team_id IS NULL AND is_default = true AND id <> $2
+		`, cal.OrganizationID, cal.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear default business calendar: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO business_calendars (id, organization_id, team_id, name, timezone, weekly_hours, holiday_set_id, is_default, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			timezone = EXCLUDED.timezone,
+			weekly_hours = EXCLUDED.weekly_hours,
+			holiday_set_id = EXCLUDED.holiday_set_id,
+			is_default = EXCLUDED.is_default,
+			active = EXCLUDED.active,
+			updated_at = NOW()
+		WHERE business_calendars.organization_id = EXCLUDED.organization_id
+		RETURNING ` + calendarColumns
+
+	saved, err := scanCalendar(tx.QueryRowContext(ctx, query,
+		cal.ID, cal.OrganizationID, cal.TeamID, cal.Name, cal.Timezone, hoursJSON,
+		cal.HolidaySetID, cal.IsDefault, cal.Active,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("business calendar %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to save business calendar: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit business calendar: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (r *CalendarRepository) DeleteCalendar(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM business_calendars WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete business calendar: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("business calendar %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *CalendarRepository) ListHolidaySets(ctx context.Context, orgID uuid.UUID) ([]types.HolidaySet, error) {
+	query := `
+		SELECT s.id, s.organization_id, s.name, s.created_at, s.updated_at
+		FROM business_holiday_sets s
+		WHERE s.organization_id = $1
+		ORDER BY s.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holiday sets: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []types.HolidaySet
+	for rows.Next() {
+		var set types.HolidaySet
+		if err := rows.Scan(&set.ID, &set.OrganizationID, &set.Name, &set.CreatedAt, &set.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday set: %w", err)
+		}
+		sets = append(sets, set)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during holiday set iteration: %w", err)
+	}
+
+	for i := range sets {
+		holidays, err := r.listHolidays(ctx, r.db, sets[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		sets[i].Holidays = holidays
+	}
+
+	return sets, nil
+}
+
+func (r *CalendarRepository) FindHolidaySet(ctx context.Context, orgID, id uuid.UUID) (*types.HolidaySet, error) {
+	var set types.HolidaySet
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, organization_id, name, created_at, updated_at
+		FROM business_holiday_sets
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, id).Scan(&set.ID, &set.OrganizationID, &set.Name, &set.CreatedAt, &set.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("holiday set %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get holiday set: %w", err)
+	}
+
+	set.Holidays, err = r.listHolidays(ctx, r.db, set.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+// SaveHolidaySet inserts or renames a holiday set and replaces its holidays
+func (r *CalendarRepository) SaveHolidaySet(ctx context.Context, set types.HolidaySet) (*types.HolidaySet, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO business_holiday_sets (id, organization_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, updated_at = NOW()
+		WHERE business_holiday_sets.organization_id = EXCLUDED.organization_id
+		RETURNING created_at, updated_at
+	`, set.ID, set.OrganizationID, set.Name).Scan(&set.CreatedAt, &set.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("holiday set %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to save holiday set: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM business_holidays WHERE holiday_set_id = $1`, set.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear holidays: %w", err)
+	}
+
+	for _, h := range set.Holidays {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO business_holidays (id, holiday_set_id, holiday_date, name)
+			VALUES ($1, $2, $3, $4)
+		`, uuid.New(), set.ID, h.Date.Format(time.DateOnly), h.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert holiday: %w", err)
+		}
+	}
+
+	set.Holidays, err = r.listHolidays(ctx, tx, set.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit holiday set: %w", err)
+	}
+
+	return &set, nil
+}
+
+func (r *CalendarRepository) DeleteHolidaySet(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM business_holiday_sets WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday set: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("holiday set %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (r *CalendarRepository) listHolidays(ctx context.Context, q queryer, setID uuid.UUID) ([]calendar.Holiday, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT holiday_date, name FROM business_holidays
+		WHERE holiday_set_id = $1
+		ORDER BY holiday_date
+	`, setID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holidays: %w", err)
+	}
+	defer rows.Close()
+
+	holidays := []calendar.Holiday{}
+	for rows.Next() {
+		var h calendar.Holiday
+		if err := rows.Scan(&h.Date, &h.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		holidays = append(holidays, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during holiday iteration: %w", err)
+	}
+
+	return holidays, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+
+	"github.com/google/uuid"
+)
+
+const workingHoursColumns = `organization_id, user_id, timezone, weekly_hours, holiday_set_id, created_at, updated_at`
+
+func scanWorkingHours(row rowScanner) (*types.UserWorkingHours, error) {
+	var hours types.UserWorkingHours
+	var hoursJSON []byte
+	err := row.Scan(
+		&hours.OrganizationID, &hours.UserID, &hours.Timezone, &hoursJSON,
+		&hours.HolidaySetID, &hours.CreatedAt, &hours.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(hoursJSON, &hours.WeeklyHours); err != nil {
+		return nil, fmt.Errorf("invalid weekly hours: %w", err)
+	}
+	return &hours, nil
+}
+
+// ListWorkingHours returns the working hours of the organization's users
+// who have some
+func (r *CalendarRepository) ListWorkingHours(ctx context.Context, orgID uuid.UUID) ([]types.UserWorkingHours, error) {
+	query := `SELECT ` + workingHoursColumns + ` FROM user_working_hours WHERE organization_id = $1 ORDER BY user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user working hours: %w", err)
+	}
+	defer rows.Close()
+
+	list := []types.UserWorkingHours{}
+	for rows.Next() {
+		hours, err := scanWorkingHours(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user working hours: %w", err)
+		}
+		list = append(list, *hours)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during user working hours iteration: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r *CalendarRepository) FindWorkingHours(ctx context.Context, orgID, userID uuid.UUID) (*types.UserWorkingHours, error) {
+	query := `SELECT ` + workingHoursColumns + ` FROM user_working_hours WHERE organization_id = $1 AND user_id = $2`
+
+	hours, err := scanWorkingHours(r.db.QueryRowContext(ctx, query, orgID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("working hours %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user working hours: %w", err)
+	}
+
+	return hours, nil
+}
+
+// SaveWorkingHours creates or replaces a user's working hours
+func (r *CalendarRepository) SaveWorkingHours(ctx context.Context, hours types.UserWorkingHours) (*types.UserWorkingHours, error) {
+	hoursJSON, err := json.Marshal(hours.WeeklyHours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal weekly hours: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_working_hours (organization_id, user_id, timezone, weekly_hours, holiday_set_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			weekly_hours = EXCLUDED.weekly_hours,
+			holiday_set_id = EXCLUDED.holiday_set_id,
+			updated_at = NOW()
+		RETURNING ` + workingHoursColumns
+
+	saved, err := scanWorkingHours(r.db.QueryRowContext(ctx, query,
+		hours.OrganizationID, hours.UserID, hours.Timezone, hoursJSON, hours.HolidaySetID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user working hours: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (r *CalendarRepository) DeleteWorkingHours(ctx context.Context, orgID, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_working_hours WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user working hours: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("working hours %w", ErrNotFound)
+	}
+
+	return nil
+}
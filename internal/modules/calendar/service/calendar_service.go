@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalid wraps validation failures of calendar and holiday set requests
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the calendar service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// CalendarService manages business calendars and the holiday sets they use
+type CalendarService struct {
+	repo        repository.CalendarRepo
+	authService AuthService
+	// fiscal holds the organizations' fiscal calendars
+	fiscal FiscalCalendarStore
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewCalendarService(repo repository.CalendarRepo, authService AuthService, logger *slog.Logger) *CalendarService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CalendarService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ListCalendars returns every calendar of the organization, defaults first
+func (s *CalendarService) ListCalendars(ctx context.Context, orgID uuid.UUID) ([]types.BusinessCalendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListCalendars(ctx, orgID)
+}
+
+// GetCalendar returns a calendar by ID
+func (s *CalendarService) GetCalendar(ctx context.Context, orgID, id uuid.UUID) (*types.BusinessCalendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindCalendar(ctx, orgID, id)
+}
+
+// CreateCalendar creates an organization-wide or team calendar
+func (s *CalendarService) CreateCalendar(ctx context.Context, orgID uuid.UUID, req types.BusinessCalendarCreateRequest) (*types.BusinessCalendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	cal := types.BusinessCalendar{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		TeamID:         req.TeamID,
+		Name:           strings.TrimSpace(req.Name),
+		Timezone:       req.Timezone,
+		WeeklyHours:    req.WeeklyHours,
+		HolidaySetID:   req.HolidaySetID,
+		IsDefault:      req.IsDefault,
+		Active:         true,
+	}
+	if req.Active != nil {
+		cal.Active = *req.Active
+	}
+
+	if err := s.validateCalendar(ctx, &cal); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveCalendar(ctx, cal)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Created business calendar", "calendar_id", saved.ID, "team_id", saved.TeamID, "is_default", saved.IsDefault)
+	return saved, nil
+}
+
+// UpdateCalendar applies a partial update to a calendar
+func (s *CalendarService) UpdateCalendar(ctx context.Context, orgID, id uuid.UUID, req types.BusinessCalendarUpdateRequest) (*types.BusinessCalendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	cal, err := s.repo.FindCalendar(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		cal.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Timezone != nil {
+		cal.Timezone = *req.Timezone
+	}
+	if req.WeeklyHours != nil {
+		cal.WeeklyHours = *req.WeeklyHours
+	}
+	if req.HolidaySetID != nil {
+		if *req.HolidaySetID == uuid.Nil {
+			cal.HolidaySetID = nil
+		} else {
+			cal.HolidaySetID = req.HolidaySetID
+		}
+	}
+	if req.IsDefault != nil {
+		cal.IsDefault = *req.IsDefault
+	}
+	if req.Active != nil {
+		cal.Active = *req.Active
+	}
+
+	if err := s.validateCalendar(ctx, cal); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveCalendar(ctx, *cal)
+}
+
+// DeleteCalendar deletes a calendar. Teams that used it fall back to the organization default.
+func (s *CalendarService) DeleteCalendar(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteCalendar(ctx, orgID, id)
+}
+
+// Effective reports which calendar applies to a team, or to the organization
+// when teamID is nil, and whether it is open now
+func (s *CalendarService) Effective(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.EffectiveCalendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	record, err := s.repo.FindEffectiveCalendar(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := calendar.Always()
+	if record != nil {
+		cal, err = s.build(ctx, *record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := s.now()
+	effective := &types.EffectiveCalendar{
+		Calendar: record,
+		Timezone: cal.Location().String(),
+		OpenNow:  cal.IsOpen(now),
+	}
+	if next, ok := cal.NextOpen(now); ok && !effective.OpenNow {
+		effective.NextOpenAt = &next
+	}
+
+	return effective, nil
+}
+
+func (s *CalendarService) build(ctx context.Context, record types.BusinessCalendar) (*calendar.Calendar, error) {
+	loc, err := time.LoadLocation(record.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", record.Timezone, err)
+	}
+
+	var holidays []calendar.Holiday
+	if record.HolidaySetID != nil {
+		set, err := s.repo.FindHolidaySet(ctx, record.OrganizationID, *record.HolidaySetID)
+		if err != nil {
+			return nil, err
+		}
+		holidays = set.Holidays
+	}
+
+	return calendar.New(loc, record.WeeklyHours, holidays)
+}
+
+func (s *CalendarService) validateCalendar(ctx context.Context, cal *types.BusinessCalendar) error {
+	if cal.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if len(cal.Name) > 255 {
+		return fmt.Errorf("%w: name must be 255 characters or less", ErrInvalid)
+	}
+	if cal.Timezone == "" {
+		cal.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(cal.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalid, cal.Timezone)
+	}
+	if err := cal.WeeklyHours.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if cal.HolidaySetID != nil {
+		if _, err := s.repo.FindHolidaySet(ctx, cal.OrganizationID, *cal.HolidaySetID); err != nil {
+			return fmt.Errorf("%w: holiday set: %v", ErrInvalid, err)
+		}
+	}
+	// Team calendars always win over the organization default for their team
+	if cal.TeamID != nil {
+		cal.IsDefault = false
+	}
+	return nil
+}
+
+// ListHolidaySets returns the organization's holiday sets with their holidays
+func (s *CalendarService) ListHolidaySets(ctx context.Context, orgID uuid.UUID) ([]types.HolidaySet, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListHolidaySets(ctx, orgID)
+}
+
+// GetHolidaySet returns a holiday set by ID
+func (s *CalendarService) GetHolidaySet(ctx context.Context, orgID, id uuid.UUID) (*types.HolidaySet, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindHolidaySet(ctx, orgID, id)
+}
+
+// CreateHolidaySet creates a holiday set
+func (s *CalendarService) CreateHolidaySet(ctx context.Context, orgID uuid.UUID, req types.HolidaySetRequest) (*types.HolidaySet, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	set := types.HolidaySet{ID: uuid.New(), OrganizationID: orgID}
+	if err := applyHolidaySetRequest(&set, req); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveHolidaySet(ctx, set)
+}
+
+// UpdateHolidaySet renames a holiday set and replaces its holidays
+func (s *CalendarService) UpdateHolidaySet(ctx context.Context, orgID, id uuid.UUID, req types.HolidaySetRequest) (*types.HolidaySet, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	set, err := s.repo.FindHolidaySet(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyHolidaySetRequest(set, req); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveHolidaySet(ctx, *set)
+}
+
+// DeleteHolidaySet deletes a holiday set. Calendars using it keep their weekly hours without holidays.
+func (s *CalendarService) DeleteHolidaySet(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteHolidaySet(ctx, orgID, id)
+}
+
+func applyHolidaySetRequest(set *types.HolidaySet, req types.HolidaySetRequest) error {
+	set.Name = strings.TrimSpace(req.Name)
+	if set.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if len(set.Name) > 255 {
+		return fmt.Errorf("%w: name must be 255 characters or less", ErrInvalid)
+	}
+
+	seen := make(map[string]bool, len(req.Holidays))
+	for _, h := range req.Holidays {
+		key := h.Date.Format(time.DateOnly)
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate holiday on %s", ErrInvalid, key)
+		}
+		seen[key] = true
+	}
+	set.Holidays = req.Holidays
+	return nil
+}
+
+// ListEvents returns the events of the users that overlap from..to
+func (s *CalendarService) ListEvents(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) ([]types.CalendarEvent, error) {
+	if err := s.authService.CheckPermission(ctx, "calendar_events:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateRange(userIDs, from, to); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(ctx, orgID, userIDs, from, to)
+}
+
+// CreateEvent adds an event to a user's calendar
+func (s *CalendarService) CreateEvent(ctx context.Context, orgID uuid.UUID, createdBy *uuid.UUID, req types.CalendarEventRequest) (*types.CalendarEvent, error) {
+	if err := s.authService.CheckPermission(ctx, "calendar_events:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	event := types.CalendarEvent{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		UserID:         req.UserID,
+		Title:          strings.TrimSpace(req.Title),
+		Description:    req.Description,
+		Location:       req.Location,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Status:         types.CalendarEventConfirmed,
+		CreatedBy:      createdBy,
+	}
+	if event.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalid)
+	}
+	if event.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	if len(event.Title) > 255 {
+		return nil, fmt.Errorf("%w: title must be 255 characters or less", ErrInvalid)
+	}
+	if !event.EndsAt.After(event.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+	}
+
+	return s.repo.CreateEvent(ctx, event)
+}
+
+// CancelEvent cancels an event, freeing its time
+func (s *CalendarService) CancelEvent(ctx context.Context, orgID, id uuid.UUID) (*types.CalendarEvent, error) {
+	if err := s.authService.CheckPermission(ctx, "calendar_events:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.CancelEvent(ctx, orgID, id)
+}
+
+// FreeBusy returns the busy time of each user in from..to, merged from
+// their events that are not cancelled
+func (s *CalendarService) FreeBusy(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) ([]types.FreeBusy, error) {
+	if err := s.authService.CheckPermission(ctx, "calendar_events:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateRange(userIDs, from, to); err != nil {
+		return nil, err
+	}
+
+	events, err := s.repo.ListEvents(ctx, orgID, userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make(map[uuid.UUID][]calendar.Span, len(userIDs))
+	for _, event := range events {
+		if event.Status == types.CalendarEventCancelled {
+			continue
+		}
+		spans := busy[event.UserID]
+		// Events come earliest first, so overlapping ones extend the last span
+		if n := len(spans); n > 0 && !event.StartsAt.After(spans[n-1].End) {
+			if event.EndsAt.After(spans[n-1].End) {
+				spans[n-1].End = event.EndsAt
+			}
+			continue
+		}
+		busy[event.UserID] = append(spans, calendar.Span{Start: event.StartsAt, End: event.EndsAt})
+	}
+
+	result := make([]types.FreeBusy, 0, len(userIDs))
+	for _, userID := range userIDs {
+		spans := busy[userID]
+		if spans == nil {
+			spans = []calendar.Span{}
+		}
+		result = append(result, types.FreeBusy{UserID: userID, Busy: spans})
+	}
+	return result, nil
+}
+
+func validateRange(userIDs []uuid.UUID, from, to time.Time) error {
+	if len(userIDs) == 0 {
+		return fmt.Errorf("%w: at least one user is required", ErrInvalid)
+	}
+	if !to.After(from) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalid)
+	}
+	if to.Sub(from) > 62*24*time.Hour {
+		return fmt.Errorf("%w: range must be 62 days or less", ErrInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCalendarRepo struct {
+	calendars map[uuid.UUID]types.BusinessCalendar
+	sets      map[uuid.UUID]types.HolidaySet
+	effective *types.BusinessCalendar
+	events    []types.CalendarEvent
+	hours     map[uuid.UUID]types.UserWorkingHours
+}
+
+func newFakeCalendarRepo() *fakeCalendarRepo {
+	return &fakeCalendarRepo{
+		calendars: make(map[uuid.UUID]types.BusinessCalendar),
+		sets:      make(map[uuid.UUID]types.HolidaySet),
+		hours:     make(map[uuid.UUID]types.UserWorkingHours),
+	}
+}
+
+func (f *fakeCalendarRepo) ListCalendars(ctx context.Context, orgID uuid.UUID) ([]types.BusinessCalendar, error) {
+	var out []types.BusinessCalendar
+	for _, c := range f.calendars {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (f *fakeCalendarRepo) FindCalendar(ctx context.Context, orgID, id uuid.UUID) (*types.BusinessCalendar, error) {
+	c, ok := f.calendars[id]
+	if !ok || c.OrganizationID != orgID {
+		return nil, fmt.Errorf("business calendar %w", repository.ErrNotFound)
+	}
+	return &c, nil
+}
+
+func (f *fakeCalendarRepo) FindEffectiveCalendar(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.BusinessCalendar, error) {
+	return f.effective, nil
+}
+
+func (f *fakeCalendarRepo) SaveCalendar(ctx context.Context, cal types.BusinessCalendar) (*types.BusinessCalendar, error) {
+	f.calendars[cal.ID] = cal
+	return &cal, nil
+}
+
+func (f *fakeCalendarRepo) DeleteCalendar(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.calendars, id)
+	return nil
+}
+
+func (f *fakeCalendarRepo) ListHolidaySets(ctx context.Context, orgID uuid.UUID) ([]types.HolidaySet, error) {
+	return nil, nil
+}
+
+func (f *fakeCalendarRepo) FindHolidaySet(ctx context.Context, orgID, id uuid.UUID) (*types.HolidaySet, error) {
+	s, ok := f.sets[id]
+	if !ok || s.OrganizationID != orgID {
+		return nil, fmt.Errorf("holiday set %w", repository.ErrNotFound)
+	}
+	return &s, nil
+}
+
+func (f *fakeCalendarRepo) SaveHolidaySet(ctx context.Context, set types.HolidaySet) (*types.HolidaySet, error) {
+	f.sets[set.ID] = set
+	return &set, nil
+}
+
+func (f *fakeCalendarRepo) DeleteHolidaySet(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.sets, id)
+	return nil
+}
+
+func (f *fakeCalendarRepo) ListEvents(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) ([]types.CalendarEvent, error) {
+	return f.events, nil
+}
+
+func (f *fakeCalendarRepo) CreateEvent(ctx context.Context, event types.CalendarEvent) (*types.CalendarEvent, error) {
+	f.events = append(f.events, event)
+	return &event, nil
+}
+
+func (f *fakeCalendarRepo) CancelEvent(ctx context.Context, orgID, id uuid.UUID) (*types.CalendarEvent, error) {
+	return nil, fmt.Errorf("calendar event %w", repository.ErrNotFound)
+}
+
+func (f *fakeCalendarRepo) ListWorkingHours(ctx context.Context, orgID uuid.UUID) ([]types.UserWorkingHours, error) {
+	var out []types.UserWorkingHours
+	for _, h := range f.hours {
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+func (f *fakeCalendarRepo) FindWorkingHours(ctx context.Context, orgID, userID uuid.UUID) (*types.UserWorkingHours, error) {
+	h, ok := f.hours[userID]
+	if !ok || h.OrganizationID != orgID {
+		return nil, fmt.Errorf("working hours %w", repository.ErrNotFound)
+	}
+	return &h, nil
+}
+
+func (f *fakeCalendarRepo) SaveWorkingHours(ctx context.Context, hours types.UserWorkingHours) (*types.UserWorkingHours, error) {
+	f.hours[hours.UserID] = hours
+	return &hours, nil
+}
+
+func (f *fakeCalendarRepo) DeleteWorkingHours(ctx context.Context, orgID, userID uuid.UUID) error {
+	delete(f.hours, userID)
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func weekdays() calendar.WeeklyHours {
+	day := []calendar.Interval{{Start: 9 * 60, End: 17 * 60}}
+	return calendar.WeeklyHours{
+		time.Monday: day, time.Tuesday: day, time.Wednesday: day, time.Thursday: day, time.Friday: day,
+	}
+}
+
+func TestCreateCalendarValidation(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	svc := NewCalendarService(newFakeCalendarRepo(), allowAll{}, nil)
+
+	_, err := svc.CreateCalendar(ctx, orgID, types.BusinessCalendarCreateRequest{Name: "Office", Timezone: "Mars/Olympus", WeeklyHours: weekdays()})
+	assert.True(t, errors.Is(err, ErrInvalid))
+
+	_, err = svc.CreateCalendar(ctx, orgID, types.BusinessCalendarCreateRequest{Name: "Office", WeeklyHours: calendar.WeeklyHours{}})
+	assert.True(t, errors.Is(err, ErrInvalid))
+
+	_, err = svc.CreateCalendar(ctx, orgID, types.BusinessCalendarCreateRequest{Name: "Office", WeeklyHours: weekdays(), HolidaySetID: ptr(uuid.New())})
+	assert.True(t, errors.Is(err, ErrInvalid), "holiday set must belong to the organization")
+
+	teamID := uuid.New()
+	cal, err := svc.CreateCalendar(ctx, orgID, types.BusinessCalendarCreateRequest{Name: "Support", TeamID: &teamID, WeeklyHours: weekdays(), IsDefault: true})
+	require.NoError(t, err)
+	assert.False(t, cal.IsDefault, "team calendars cannot be the organization default")
+	assert.Equal(t, "UTC", cal.Timezone)
+	assert.True(t, cal.Active)
+}
+
+func TestHolidaySetRejectsDuplicateDates(t *testing.T) {
+	svc := NewCalendarService(newFakeCalendarRepo(), allowAll{}, nil)
+	day := time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)
+
+	_, err := svc.CreateHolidaySet(context.Background(), uuid.New(), types.HolidaySetRequest{
+		Name:     "Public holidays",
+		Holidays: []calendar.Holiday{{Date: day, Name: "Christmas"}, {Date: day, Name: "Again"}},
+	})
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func TestEffectiveUsesHolidaysAndTimezone(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakeCalendarRepo()
+	svc := NewCalendarService(repo, allowAll{}, nil)
+
+	set, err := svc.CreateHolidaySet(ctx, orgID, types.HolidaySetRequest{
+		Name:     "Closures",
+		Holidays: []calendar.Holiday{{Date: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), Name: "Inventory day"}},
+	})
+	require.NoError(t, err)
+
+	cal, err := svc.CreateCalendar(ctx, orgID, types.BusinessCalendarCreateRequest{
+		Name: "Office", Timezone: "America/New_York", WeeklyHours: weekdays(), HolidaySetID: &set.ID, IsDefault: true,
+	})
+	require.NoError(t, err)
+	repo.effective = cal
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Monday 10:00 in New York is a closure day
+	svc.now = func() time.Time { return time.Date(2025, 3, 3, 10, 0, 0, 0, ny) }
+	effective, err := svc.Effective(ctx, orgID, nil)
+	require.NoError(t, err)
+	assert.False(t, effective.OpenNow)
+	assert.Equal(t, "America/New_York", effective.Timezone)
+	require.NotNil(t, effective.NextOpenAt)
+	assert.True(t, effective.NextOpenAt.Equal(time.Date(2025, 3, 4, 9, 0, 0, 0, ny)))
+
+	// Without a configured calendar the organization is always open
+	repo.effective = nil
+	effective, err = svc.Effective(ctx, orgID, nil)
+	require.NoError(t, err)
+	assert.True(t, effective.OpenNow)
+	assert.Nil(t, effective.Calendar)
+}
+
+func TestWorkingHoursShift(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	repo := newFakeCalendarRepo()
+	svc := NewCalendarService(repo, allowAll{}, nil)
+
+	_, err := svc.SetWorkingHours(ctx, orgID, userID, types.UserWorkingHoursRequest{Timezone: "Mars/Olympus", WeeklyHours: weekdays()})
+	assert.True(t, errors.Is(err, ErrInvalid))
+
+	_, err = svc.SetWorkingHours(ctx, orgID, userID, types.UserWorkingHoursRequest{WeeklyHours: weekdays(), HolidaySetID: ptr(uuid.New())})
+	assert.True(t, errors.Is(err, ErrInvalid), "holiday set must belong to the organization")
+
+	set, err := svc.CreateHolidaySet(ctx, orgID, types.HolidaySetRequest{
+		Name:     "Closures",
+		Holidays: []calendar.Holiday{{Date: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC), Name: "Inventory day"}},
+	})
+	require.NoError(t, err)
+	repo.effective = &types.BusinessCalendar{OrganizationID: orgID, HolidaySetID: &set.ID}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// Tuesday 10:00 in Tokyo is 01:00 UTC, but the user works in Tokyo
+	svc.now = func() time.Time { return time.Date(2025, 3, 4, 10, 0, 0, 0, tokyo) }
+	hours, err := svc.SetWorkingHours(ctx, orgID, userID, types.UserWorkingHoursRequest{Timezone: "Asia/Tokyo", WeeklyHours: weekdays()})
+	require.NoError(t, err)
+	assert.True(t, hours.OnShift)
+	assert.Nil(t, hours.NextShiftAt)
+
+	// Monday is a closure day of the organization default calendar
+	svc.now = func() time.Time { return time.Date(2025, 3, 3, 10, 0, 0, 0, tokyo) }
+	hours, err = svc.GetWorkingHours(ctx, orgID, userID)
+	require.NoError(t, err)
+	assert.False(t, hours.OnShift)
+	require.NotNil(t, hours.NextShiftAt)
+	assert.True(t, hours.NextShiftAt.Equal(time.Date(2025, 3, 4, 9, 0, 0, 0, tokyo)))
+}
+
+func TestFreeBusyMergesOverlappingEvents(t *testing.T) {
+	ctx := context.Background()
+	userID, other := uuid.New(), uuid.New()
+	at := func(h, m int) time.Time { return time.Date(2025, 3, 3, h, m, 0, 0, time.UTC) }
+
+	repo := newFakeCalendarRepo()
+	repo.events = []types.CalendarEvent{
+		{UserID: userID, StartsAt: at(9, 0), EndsAt: at(10, 0), Status: types.CalendarEventConfirmed},
+		{UserID: userID, StartsAt: at(9, 30), EndsAt: at(10, 30), Status: types.CalendarEventConfirmed},
+		{UserID: userID, StartsAt: at(11, 0), EndsAt: at(12, 0), Status: types.CalendarEventCancelled},
+		{UserID: userID, StartsAt: at(14, 0), EndsAt: at(15, 0), Status: types.CalendarEventConfirmed},
+	}
+	svc := NewCalendarService(repo, allowAll{}, nil)
+
+	freeBusy, err := svc.FreeBusy(ctx, uuid.New(), []uuid.UUID{userID, other}, at(0, 0), at(23, 0))
+	require.NoError(t, err)
+	require.Len(t, freeBusy, 2)
+	assert.Equal(t, []calendar.Span{{Start: at(9, 0), End: at(10, 30)}, {Start: at(14, 0), End: at(15, 0)}}, freeBusy[0].Busy)
+	assert.Empty(t, freeBusy[1].Busy)
+
+	_, err = svc.FreeBusy(ctx, uuid.New(), []uuid.UUID{userID}, at(10, 0), at(9, 0))
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+)
+
+// FiscalCalendarStore loads and saves organizations' fiscal calendars
+type FiscalCalendarStore interface {
+	Find(ctx context.Context, orgID uuid.UUID) (*fiscal.Calendar, error)
+	Save(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, cal fiscal.Calendar) error
+}
+
+// SetFiscalCalendars enables configuring the organization's fiscal year
+func (s *CalendarService) SetFiscalCalendars(store FiscalCalendarStore) {
+	s.fiscal = store
+}
+
+// GetFiscalCalendar returns the organization's fiscal calendar, calendar
+// years of calendar months when it has not configured one
+func (s *CalendarService) GetFiscalCalendar(ctx context.Context, orgID uuid.UUID) (*fiscal.Calendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.fiscal.Find(ctx, orgID)
+}
+
+// SetFiscalCalendar creates or replaces the organization's fiscal calendar.
+// Fiscal period parameters of analytics resolve through it from then on.
+func (s *CalendarService) SetFiscalCalendar(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, cal fiscal.Calendar) (*fiscal.Calendar, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := cal.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := s.fiscal.Save(ctx, orgID, userID, cal); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Fiscal calendar updated", "organization_id", orgID, "start_month", cal.StartMonth, "pattern", cal.Pattern)
+	return &cal, nil
+}
+
+// FiscalYear lays out a fiscal year of the organization, the current one
+// when fy is zero
+func (s *CalendarService) FiscalYear(ctx context.Context, orgID uuid.UUID, fy int) (*types.FiscalYear, error) {
+	cal, err := s.GetFiscalCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if fy == 0 {
+		fy = cal.FiscalYearOf(s.now().UTC())
+	}
+	if fy < 1900 || fy > 9999 {
+		return nil, fmt.Errorf("%w: invalid fiscal year %d", ErrInvalid, fy)
+	}
+
+	year := &types.FiscalYear{Year: cal.Year(fy), Periods: cal.Periods(fy)}
+	for q := 1; q <= 4; q++ {
+		year.Quarters = append(year.Quarters, cal.Quarter(fy, q))
+	}
+	return year, nil
+}
+
+// ResolveFiscalPeriod returns the period a fiscal period parameter names,
+// as analytics read it
+func (s *CalendarService) ResolveFiscalPeriod(ctx context.Context, orgID uuid.UUID, spec string) (*fiscal.Period, error) {
+	cal, err := s.GetFiscalCalendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	period, err := cal.Parse(spec, s.now().UTC())
+	if errors.Is(err, fiscal.ErrInvalidPeriod) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
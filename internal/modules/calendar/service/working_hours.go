@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calendar/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+)
+
+// ListWorkingHours returns the working hours of the organization's users
+// who have some, with whether they are on shift now
+func (s *CalendarService) ListWorkingHours(ctx context.Context, orgID uuid.UUID) ([]types.UserWorkingHours, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	list, err := s.repo.ListWorkingHours(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if err := s.shift(ctx, &list[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// GetWorkingHours returns a user's working hours and whether they are on
+// shift now
+func (s *CalendarService) GetWorkingHours(ctx context.Context, orgID, userID uuid.UUID) (*types.UserWorkingHours, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	hours, err := s.repo.FindWorkingHours(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.shift(ctx, hours); err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// SetWorkingHours creates or replaces a user's working hours. Automatic
+// assignment only picks the user during them from then on.
+func (s *CalendarService) SetWorkingHours(ctx context.Context, orgID, userID uuid.UUID, req types.UserWorkingHoursRequest) (*types.UserWorkingHours, error) {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	hours := types.UserWorkingHours{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Timezone:       req.Timezone,
+		WeeklyHours:    req.WeeklyHours,
+		HolidaySetID:   req.HolidaySetID,
+	}
+	if hours.Timezone == "" {
+		hours.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(hours.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalid, hours.Timezone)
+	}
+	if err := hours.WeeklyHours.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if hours.HolidaySetID != nil {
+		if _, err := s.repo.FindHolidaySet(ctx, orgID, *hours.HolidaySetID); err != nil {
+			return nil, fmt.Errorf("%w: holiday set: %v", ErrInvalid, err)
+		}
+	}
+
+	saved, err := s.repo.SaveWorkingHours(ctx, hours)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.shift(ctx, saved); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User working hours updated", "organization_id", orgID, "user_id", userID, "timezone", saved.Timezone)
+	return saved, nil
+}
+
+// DeleteWorkingHours removes a user's working hours, putting them on shift
+// around the clock
+func (s *CalendarService) DeleteWorkingHours(ctx context.Context, orgID, userID uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "business_calendars:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteWorkingHours(ctx, orgID, userID)
+}
+
+// shift reports whether the user is on shift now and, when they are not,
+// when their next shift starts. Users without their own holiday set observe
+// the holidays of the organization default calendar.
+func (s *CalendarService) shift(ctx context.Context, hours *types.UserWorkingHours) error {
+	loc, err := time.LoadLocation(hours.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", hours.Timezone, err)
+	}
+
+	setID := hours.HolidaySetID
+	if setID == nil {
+		def, err := s.repo.FindEffectiveCalendar(ctx, hours.OrganizationID, nil)
+		if err != nil {
+			return err
+		}
+		if def != nil {
+			setID = def.HolidaySetID
+		}
+	}
+	var holidays []calendar.Holiday
+	if setID != nil {
+		set, err := s.repo.FindHolidaySet(ctx, hours.OrganizationID, *setID)
+		if err != nil {
+			return err
+		}
+		holidays = set.Holidays
+	}
+
+	cal, err := calendar.New(loc, hours.WeeklyHours, holidays)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	hours.OnShift = cal.IsOpen(now)
+	hours.NextShiftAt = nil
+	if next, ok := cal.NextOpen(now); ok && !hours.OnShift {
+		hours.NextShiftAt = &next
+	}
+	return nil
+}
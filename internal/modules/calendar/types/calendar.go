@@ -0,0 +1,147 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+)
+
+// BusinessCalendar defines working hours for an organization or one of its
+// sales teams. The organization-wide default applies to teams without their
+// own calendar.
+type BusinessCalendar struct {
+	ID             uuid.UUID            `json:"id" db:"id"`
+	OrganizationID uuid.UUID            `json:"organization_id" db:"organization_id"`
+	TeamID         *uuid.UUID           `json:"team_id,omitempty" db:"team_id"`
+	Name           string               `json:"name" db:"name"`
+	Timezone       string               `json:"timezone" db:"timezone"`
+	WeeklyHours    calendar.WeeklyHours `json:"weekly_hours" db:"weekly_hours"`
+	HolidaySetID   *uuid.UUID           `json:"holiday_set_id,omitempty" db:"holiday_set_id"`
+	IsDefault      bool                 `json:"is_default" db:"is_default"`
+	Active         bool                 `json:"active" db:"active"`
+	CreatedAt      time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// BusinessCalendarCreateRequest represents a request to create a business calendar
+type BusinessCalendarCreateRequest struct {
+	Name         string               `json:"name"`
+	TeamID       *uuid.UUID           `json:"team_id,omitempty"`
+	Timezone     string               `json:"timezone"`
+	WeeklyHours  calendar.WeeklyHours `json:"weekly_hours"`
+	HolidaySetID *uuid.UUID           `json:"holiday_set_id,omitempty"`
+	IsDefault    bool                 `json:"is_default"`
+	Active       *bool                `json:"active,omitempty"`
+}
+
+// BusinessCalendarUpdateRequest represents a request to update a business calendar
+type BusinessCalendarUpdateRequest struct {
+	Name         *string               `json:"name,omitempty"`
+	Timezone     *string               `json:"timezone,omitempty"`
+	WeeklyHours  *calendar.WeeklyHours `json:"weekly_hours,omitempty"`
+	HolidaySetID *uuid.UUID            `json:"holiday_set_id,omitempty"`
+	IsDefault    *bool                 `json:"is_default,omitempty"`
+	Active       *bool                 `json:"active,omitempty"`
+}
+
+// HolidaySet is a named list of non-working days shared by business calendars
+type HolidaySet struct {
+	ID             uuid.UUID          `json:"id" db:"id"`
+	OrganizationID uuid.UUID          `json:"organization_id" db:"organization_id"`
+	Name           string             `json:"name" db:"name"`
+	Holidays       []calendar.Holiday `json:"holidays"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// HolidaySetRequest creates a holiday set or replaces its name and holidays
+type HolidaySetRequest struct {
+	Name     string             `json:"name"`
+	Holidays []calendar.Holiday `json:"holidays"`
+}
+
+// EffectiveCalendar reports the calendar that applies to a team right now
+type EffectiveCalendar struct {
+	// Calendar is nil when no calendar is configured and the organization is treated as always open
+	Calendar   *BusinessCalendar `json:"calendar"`
+	Timezone   string            `json:"timezone"`
+	OpenNow    bool              `json:"open_now"`
+	NextOpenAt *time.Time        `json:"next_open_at,omitempty"`
+}
+
+// UserWorkingHours are the shifts of a user in their own time zone.
+// Automatic assignment passes over users outside their working hours.
+type UserWorkingHours struct {
+	OrganizationID uuid.UUID            `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID            `json:"user_id" db:"user_id"`
+	Timezone       string               `json:"timezone" db:"timezone"`
+	WeeklyHours    calendar.WeeklyHours `json:"weekly_hours" db:"weekly_hours"`
+	// HolidaySetID is nil for users observing the holidays of the
+	// organization default calendar
+	HolidaySetID *uuid.UUID `json:"holiday_set_id,omitempty" db:"holiday_set_id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	// OnShift and NextShiftAt report the user's shift when read
+	OnShift     bool       `json:"on_shift"`
+	NextShiftAt *time.Time `json:"next_shift_at,omitempty"`
+}
+
+// UserWorkingHoursRequest sets or replaces a user's working hours
+type UserWorkingHoursRequest struct {
+	Timezone     string               `json:"timezone"`
+	WeeklyHours  calendar.WeeklyHours `json:"weekly_hours"`
+	HolidaySetID *uuid.UUID           `json:"holiday_set_id,omitempty"`
+}
+
+// CalendarEventStatus is whether an event still takes place
+type CalendarEventStatus string
+
+const (
+	CalendarEventConfirmed CalendarEventStatus = "confirmed"
+	CalendarEventCancelled CalendarEventStatus = "cancelled"
+)
+
+// CalendarEvent is a meeting or other busy time on a user's calendar
+type CalendarEvent struct {
+	ID             uuid.UUID           `json:"id" db:"id"`
+	OrganizationID uuid.UUID           `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID           `json:"user_id" db:"user_id"`
+	Title          string              `json:"title" db:"title"`
+	Description    *string             `json:"description,omitempty" db:"description"`
+	Location       *string             `json:"location,omitempty" db:"location"`
+	StartsAt       time.Time           `json:"starts_at" db:"starts_at"`
+	EndsAt         time.Time           `json:"ends_at" db:"ends_at"`
+	Status         CalendarEventStatus `json:"status" db:"status"`
+	// ResModel and ResID link the event to the record it is about
+	ResModel  *string    `json:"res_model,omitempty" db:"res_model"`
+	ResID     *uuid.UUID `json:"res_id,omitempty" db:"res_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+// CalendarEventRequest represents a request to create a calendar event
+type CalendarEventRequest struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description,omitempty"`
+	Location    *string   `json:"location,omitempty"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+}
+
+// FreeBusy lists the busy time of a user in a period
+type FreeBusy struct {
+	UserID uuid.UUID       `json:"user_id"`
+	Busy   []calendar.Span `json:"busy"`
+}
+
+// FiscalYear is a fiscal year with its quarters and periods
+type FiscalYear struct {
+	Year     fiscal.Period   `json:"year"`
+	Quarters []fiscal.Period `json:"quarters"`
+	Periods  []fiscal.Period `json:"periods"`
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxDueWindowDays bounds the within_days of the due list
+const maxDueWindowDays = 730
+
+// CalibrationHandler handles measuring equipment, its calibrations and
+// their due dates
+type CalibrationHandler struct {
+	service *service.CalibrationService
+}
+
+func NewCalibrationHandler(service *service.CalibrationService) *CalibrationHandler {
+	return &CalibrationHandler{service: service}
+}
+
+func (h *CalibrationHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/equipment", h.ListEquipment)
+	router.POST("/api/equipment", h.CreateEquipment)
+	router.GET("/api/equipment/:id", h.GetEquipment)
+	router.PUT("/api/equipment/:id", h.UpdateEquipment)
+	router.GET("/api/equipment/:id/calibrations", h.ListCalibrations)
+	router.POST("/api/equipment/:id/calibrations", h.RecordCalibration)
+	router.GET("/api/equipment/:id/usage", h.ListUsage)
+
+	router.GET("/api/calibration-due", h.ListDue)
+	router.POST("/api/calibration-due", h.CheckDue)
+}
+
+// ListEquipment handles GET /api/equipment, filtered by status and
+// equipment_type
+func (h *CalibrationHandler) ListEquipment(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	equipment, err := h.service.ListEquipment(r.Context(), types.EquipmentFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.EquipmentStatus(q.Get("status")),
+		EquipmentType:  q.Get("equipment_type"),
+	})
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, equipment)
+}
+
+// CreateEquipment handles POST /api/equipment
+func (h *CalibrationHandler) CreateEquipment(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.EquipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	equipment, err := h.service.CreateEquipment(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, equipment)
+}
+
+// GetEquipment handles GET /api/equipment/:id
+func (h *CalibrationHandler) GetEquipment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment ID", http.StatusBadRequest)
+		return
+	}
+
+	equipment, err := h.service.GetEquipment(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, equipment)
+}
+
+// UpdateEquipment handles PUT /api/equipment/:id
+func (h *CalibrationHandler) UpdateEquipment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.EquipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	equipment, err := h.service.UpdateEquipment(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, equipment)
+}
+
+// ListCalibrations handles GET /api/equipment/:id/calibrations
+func (h *CalibrationHandler) ListCalibrations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment ID", http.StatusBadRequest)
+		return
+	}
+
+	calibrations, err := h.service.ListCalibrations(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, calibrations)
+}
+
+// RecordCalibration handles POST /api/equipment/:id/calibrations
+func (h *CalibrationHandler) RecordCalibration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CalibrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	calibration, err := h.service.RecordCalibration(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, calibration)
+}
+
+// ListUsage handles GET /api/equipment/:id/usage, listing the inspection
+// items measured with the equipment, on or after since (RFC 3339) when set
+func (h *CalibrationHandler) ListUsage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment ID", http.StatusBadRequest)
+		return
+	}
+
+	var since *time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = &t
+	}
+
+	usage, err := h.service.ListUsage(r.Context(), authCtx.OrganizationID, id, since)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// ListDue handles GET /api/calibration-due, listing the active equipment
+// due for calibration within_days from today, 14 by default. Overdue and
+// never calibrated equipment is included.
+func (h *CalibrationHandler) ListDue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	days := service.DefaultAlertDays
+	if v := r.URL.Query().Get("within_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxDueWindowDays {
+			http.Error(w, "Invalid within_days", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	equipment, err := h.service.ListDue(r.Context(), authCtx.OrganizationID, days)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, equipment)
+}
+
+// CheckDue handles POST /api/calibration-due, raising the due alerts that
+// are due without waiting for the scheduled check
+func (h *CalibrationHandler) CheckDue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CheckDue(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCalibrationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCalibrationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeDueAlerts = "calibration.due_alerts"
+
+// DueAlertJobHandler handles queued calibration due checks
+type DueAlertJobHandler struct {
+	calibrationService *service.CalibrationService
+}
+
+func NewDueAlertJobHandler(calibrationService *service.CalibrationService) *DueAlertJobHandler {
+	return &DueAlertJobHandler{
+		calibrationService: calibrationService,
+	}
+}
+
+// Handle processes a calibration due check job
+func (h *DueAlertJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.calibrationService.CheckAll(ctx); err != nil {
+		return fmt.Errorf("failed to check calibration due dates: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DueAlertJobHandler) JobType() string {
+	return JobTypeDueAlerts
+}
+
+// Scheduler checks equipment on a fixed interval, so calibrations are
+// scheduled before instruments fall due
+type Scheduler struct {
+	handler     *DueAlertJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DueAlertJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start checks equipment every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeDueAlerts, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeDueAlerts, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled calibration due check failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule calibration due check", "error", err)
+	}
+}
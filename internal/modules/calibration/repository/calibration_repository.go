@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when equipment or a calibration does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when an equipment code is already used
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when calibrating retired equipment
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// CalibrationRepo defines the interface for calibration repository operations
+type CalibrationRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.Equipment, error)
+	FindEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.Equipment, error)
+	CreateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error)
+	UpdateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error)
+	ListCalibrations(ctx context.Context, orgID, equipmentID uuid.UUID) ([]types.Calibration, error)
+	RecordCalibration(ctx context.Context, userID *uuid.UUID, calibration types.Calibration) (*types.Calibration, *types.Equipment, error)
+	ListUsage(ctx context.Context, orgID, equipmentID uuid.UUID, since *time.Time) ([]types.Usage, error)
+	ClaimDueAlerts(ctx context.Context, orgID uuid.UUID, day time.Time) ([]types.DueAlert, error)
+}
+
+// CalibrationRepository stores equipment, its calibrations and where it
+// was used
+type CalibrationRepository struct {
+	db *sql.DB
+}
+
+// Ensure CalibrationRepository implements CalibrationRepo interface
+var _ CalibrationRepo = &CalibrationRepository{}
+
+func NewCalibrationRepository(db *sql.DB) *CalibrationRepository {
+	return &CalibrationRepository{db: db}
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// ListOrganizationIDs returns the organizations with active equipment whose
+// current due date has not been alerted
+func (r *CalibrationRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT organization_id FROM calibration_equipment
+		WHERE status = 'active' AND next_due_on IS NOT NULL
+			AND alerted_due_on IS DISTINCT FROM next_due_on`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const equipmentColumns = `id, organization_id, code, name, COALESCE(equipment_type, ''), COALESCE(manufacturer, ''),
+	COALESCE(model, ''), COALESCE(serial_number, ''), COALESCE(location, ''), asset_id, calibration_interval_days,
+	alert_days, status, last_calibrated_on, COALESCE(last_result, ''), next_due_on, alerted_due_on,
+	COALESCE(notes, ''), created_at, updated_at`
+
+func scanEquipment(row interface{ Scan(...interface{}) error }) (*types.Equipment, error) {
+	var e types.Equipment
+	var assetID uuid.NullUUID
+	var lastCalibratedOn, nextDueOn, alertedDueOn sql.NullTime
+	if err := row.Scan(&e.ID, &e.OrganizationID, &e.Code, &e.Name, &e.EquipmentType, &e.Manufacturer, &e.Model,
+		&e.SerialNumber, &e.Location, &assetID, &e.CalibrationIntervalDays, &e.AlertDays, &e.Status,
+		&lastCalibratedOn, &e.LastResult, &nextDueOn, &alertedDueOn, &e.Notes, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	e.AssetID = nullUUID(assetID)
+	e.LastCalibratedOn = nullTime(lastCalibratedOn)
+	e.NextDueOn = nullTime(nextDueOn)
+	e.AlertedDueOn = nullTime(alertedDueOn)
+	return &e, nil
+}
+
+// ListEquipment returns the equipment matching the filter, soonest due first
+func (r *CalibrationRepository) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.Equipment, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.EquipmentType != "" {
+		args = append(args, filter.EquipmentType)
+		where = append(where, fmt.Sprintf("equipment_type = $%d", len(args)))
+	}
+	if filter.DueBefore != nil {
+		args = append(args, *filter.DueBefore)
+		where = append(where, fmt.Sprintf("status = 'active' AND (next_due_on IS NULL OR next_due_on <= $%d)", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+equipmentColumns+` FROM calibration_equipment
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY next_due_on NULLS FIRST, code, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list equipment: %w", err)
+	}
+	defer rows.Close()
+
+	equipment := []types.Equipment{}
+	for rows.Next() {
+		e, err := scanEquipment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan equipment: %w", err)
+		}
+		equipment = append(equipment, *e)
+	}
+	return equipment, rows.Err()
+}
+
+func (r *CalibrationRepository) FindEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.Equipment, error) {
+	e, err := scanEquipment(r.db.QueryRowContext(ctx, `
+		SELECT `+equipmentColumns+` FROM calibration_equipment WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("equipment %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find equipment: %w", err)
+	}
+	return e, nil
+}
+
+// CreateEquipment adds equipment, which is due for calibration until its
+// first one is recorded
+func (r *CalibrationRepository) CreateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error) {
+	e, err := scanEquipment(r.db.QueryRowContext(ctx, `
+		INSERT INTO calibration_equipment (
+			organization_id, code, name, equipment_type, manufacturer, model, serial_number, location, asset_id,
+			calibration_interval_days, alert_days, status, notes, created_by, updated_by
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9,
+			$10, $11, $12, NULLIF($13, ''), $14, $14)
+		RETURNING `+equipmentColumns,
+		equipment.OrganizationID, equipment.Code, equipment.Name, equipment.EquipmentType, equipment.Manufacturer,
+		equipment.Model, equipment.SerialNumber, equipment.Location, equipment.AssetID,
+		equipment.CalibrationIntervalDays, equipment.AlertDays, string(equipment.Status), equipment.Notes, userID))
+	if err != nil {
+		if isUniqueViolation(err, "calibration_equipment_code_key") {
+			return nil, fmt.Errorf("equipment %s %w", equipment.Code, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create equipment: %w", err)
+	}
+	return e, nil
+}
+
+// UpdateEquipment replaces the details and status of equipment. Its
+// calibration dates only change with its calibrations.
+func (r *CalibrationRepository) UpdateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error) {
+	e, err := scanEquipment(r.db.QueryRowContext(ctx, `
+		UPDATE calibration_equipment SET
+			code = $3, name = $4, equipment_type = NULLIF($5, ''), manufacturer = NULLIF($6, ''),
+			model = NULLIF($7, ''), serial_number = NULLIF($8, ''), location = NULLIF($9, ''), asset_id = $10,
+			calibration_interval_days = $11, alert_days = $12, status = $13, notes = NULLIF($14, ''),
+			updated_at = now(), updated_by = $15
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+equipmentColumns,
+		equipment.OrganizationID, equipment.ID, equipment.Code, equipment.Name, equipment.EquipmentType,
+		equipment.Manufacturer, equipment.Model, equipment.SerialNumber, equipment.Location, equipment.AssetID,
+		equipment.CalibrationIntervalDays, equipment.AlertDays, string(equipment.Status), equipment.Notes, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("equipment %w", ErrNotFound)
+		}
+		if isUniqueViolation(err, "calibration_equipment_code_key") {
+			return nil, fmt.Errorf("equipment %s %w", equipment.Code, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update equipment: %w", err)
+	}
+	return e, nil
+}
+
+const calibrationColumns = `id, organization_id, equipment_id, calibrated_on, result, COALESCE(performed_by, ''),
+	COALESCE(certificate_number, ''), COALESCE(certificate_url, ''), next_due_on, COALESCE(notes, ''),
+	created_at, created_by`
+
+func scanCalibration(row interface{ Scan(...interface{}) error }) (*types.Calibration, error) {
+	var c types.Calibration
+	var nextDueOn sql.NullTime
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&c.ID, &c.OrganizationID, &c.EquipmentID, &c.CalibratedOn, &c.Result, &c.PerformedBy,
+		&c.CertificateNumber, &c.CertificateURL, &nextDueOn, &c.Notes, &c.CreatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	c.NextDueOn = nullTime(nextDueOn)
+	c.CreatedBy = nullUUID(createdBy)
+	return &c, nil
+}
+
+// ListCalibrations returns the calibration history of equipment, latest
+// first
+func (r *CalibrationRepository) ListCalibrations(ctx context.Context, orgID, equipmentID uuid.UUID) ([]types.Calibration, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+calibrationColumns+` FROM equipment_calibrations
+		WHERE organization_id = $1 AND equipment_id = $2
+		ORDER BY calibrated_on DESC, created_at DESC
+	`, orgID, equipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calibrations: %w", err)
+	}
+	defer rows.Close()
+
+	calibrations := []types.Calibration{}
+	for rows.Next() {
+		c, err := scanCalibration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calibration: %w", err)
+		}
+		calibrations = append(calibrations, *c)
+	}
+	return calibrations, rows.Err()
+}
+
+// RecordCalibration adds a calibration to the history of equipment. When it
+// is the latest one, a passed calibration returns the equipment to service
+// until its due date and a failed one takes it out of service; older ones
+// only add to the history.
+func (r *CalibrationRepository) RecordCalibration(ctx context.Context, userID *uuid.UUID, calibration types.Calibration) (*types.Calibration, *types.Equipment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status types.EquipmentStatus
+	var lastCalibratedOn sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, last_calibrated_on FROM calibration_equipment
+		WHERE organization_id = $1 AND id = $2
+		FOR UPDATE
+	`, calibration.OrganizationID, calibration.EquipmentID).Scan(&status, &lastCalibratedOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("equipment %w", ErrNotFound)
+		}
+		return nil, nil, fmt.Errorf("failed to lock equipment: %w", err)
+	}
+	if status == types.EquipmentRetired {
+		return nil, nil, fmt.Errorf("equipment is retired: %w", ErrInvalidState)
+	}
+
+	created, err := scanCalibration(tx.QueryRowContext(ctx, `
+		INSERT INTO equipment_calibrations (
+			organization_id, equipment_id, calibrated_on, result, performed_by, certificate_number,
+			certificate_url, next_due_on, notes, created_by
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), $10)
+		RETURNING `+calibrationColumns,
+		calibration.OrganizationID, calibration.EquipmentID, calibration.CalibratedOn, string(calibration.Result),
+		calibration.PerformedBy, calibration.CertificateNumber, calibration.CertificateURL, calibration.NextDueOn,
+		calibration.Notes, userID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record calibration: %w", err)
+	}
+
+	if !lastCalibratedOn.Valid || !calibration.CalibratedOn.Before(lastCalibratedOn.Time) {
+		if calibration.Result.Passed() {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE calibration_equipment SET
+					status = 'active', last_calibrated_on = $3, last_result = $4, next_due_on = $5,
+					alerted_due_on = NULL, updated_at = now(), updated_by = $6
+				WHERE organization_id = $1 AND id = $2
+			`, calibration.OrganizationID, calibration.EquipmentID, calibration.CalibratedOn,
+				string(calibration.Result), calibration.NextDueOn, userID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE calibration_equipment SET
+					status = 'out_of_service', last_calibrated_on = $3, last_result = $4,
+					updated_at = now(), updated_by = $5
+				WHERE organization_id = $1 AND id = $2
+			`, calibration.OrganizationID, calibration.EquipmentID, calibration.CalibratedOn,
+				string(calibration.Result), userID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update equipment calibration: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit calibration: %w", err)
+	}
+
+	equipment, err := r.FindEquipment(ctx, calibration.OrganizationID, calibration.EquipmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, equipment, nil
+}
+
+// ListUsage returns the inspection items measured with equipment, latest
+// first, optionally only those measured on or after since
+func (r *CalibrationRepository) ListUsage(ctx context.Context, orgID, equipmentID uuid.UUID, since *time.Time) ([]types.Usage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT it.id, i.id, i.product_id, i.product_name, it.description, it.result, it.created_at
+		FROM quality_control_inspection_items it
+		JOIN quality_control_inspections i ON i.id = it.inspection_id
+		WHERE i.organization_id = $1 AND it.equipment_id = $2
+			AND ($3::timestamptz IS NULL OR it.created_at >= $3)
+		ORDER BY it.created_at DESC, it.id
+	`, orgID, equipmentID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list equipment usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []types.Usage{}
+	for rows.Next() {
+		var u types.Usage
+		if err := rows.Scan(&u.InspectionItemID, &u.InspectionID, &u.ProductID, &u.ProductName, &u.Description,
+			&u.Result, &u.MeasuredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan equipment usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// ClaimDueAlerts marks the due dates of active equipment that entered their
+// alert window by the day as alerted, and returns them. Marking them in one
+// statement lets every instance run the check.
+func (r *CalibrationRepository) ClaimDueAlerts(ctx context.Context, orgID uuid.UUID, day time.Time) ([]types.DueAlert, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE calibration_equipment SET alerted_due_on = next_due_on
+		WHERE organization_id = $1
+			AND status = 'active'
+			AND next_due_on IS NOT NULL
+			AND next_due_on <= $2::date + alert_days
+			AND alerted_due_on IS DISTINCT FROM next_due_on
+		RETURNING id, organization_id, code, name, COALESCE(location, ''), next_due_on, next_due_on < $2::date
+	`, orgID, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim calibration due alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []types.DueAlert{}
+	for rows.Next() {
+		var a types.DueAlert
+		if err := rows.Scan(&a.EquipmentID, &a.OrganizationID, &a.Code, &a.Name, &a.Location, &a.DueOn,
+			&a.Overdue); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration due alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
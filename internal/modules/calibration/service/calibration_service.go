@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CheckInterval is how often equipment is checked for due calibrations
+	CheckInterval = 6 * time.Hour
+	// DefaultAlertDays is the alert window of equipment that does not set one
+	DefaultAlertDays = 14
+	// MaxAlertDays bounds the alert window of equipment
+	MaxAlertDays = 365
+	// MaxIntervalDays bounds the calibration interval of equipment
+	MaxIntervalDays = 3650
+	// EventCalibrationRecorded is published when a calibration is recorded
+	EventCalibrationRecorded = "calibration.recorded"
+	// EventCalibrationFailed is published when a calibration fails and takes
+	// its equipment out of service, for reviewing what it measured
+	EventCalibrationFailed = "calibration.failed"
+	// EventCalibrationDue is published once for each due date equipment
+	// reaches the alert window of
+	EventCalibrationDue = "calibration.due"
+)
+
+var (
+	// ErrInvalid wraps validation failures of equipment and calibrations
+	ErrInvalid = errors.New("invalid request")
+	// ErrNotCalibrated is returned when measuring with equipment that is out
+	// of service or past its calibration due date
+	ErrNotCalibrated = errors.New("not calibrated")
+)
+
+// AuthService defines the permission check used by the calibration service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// CalibrationService tracks measuring equipment, its calibrations and their
+// due dates, and tells whether equipment may be used for measurements
+type CalibrationService struct {
+	repo        repository.CalibrationRepo
+	authService AuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewCalibrationService(repo repository.CalibrationRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *CalibrationService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CalibrationService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// today is the current date at midnight UTC
+func (s *CalibrationService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+func (s *CalibrationService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish calibration event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListEquipment returns the equipment matching the filter
+func (s *CalibrationService) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.Equipment, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	return s.repo.ListEquipment(ctx, filter)
+}
+
+// ListDue returns the active equipment due for calibration within days from
+// today, including overdue and never calibrated equipment
+func (s *CalibrationService) ListDue(ctx context.Context, orgID uuid.UUID, days int) ([]types.Equipment, error) {
+	before := s.today().AddDate(0, 0, days)
+	return s.ListEquipment(ctx, types.EquipmentFilter{
+		OrganizationID: orgID,
+		DueBefore:      &before,
+	})
+}
+
+// GetEquipment returns equipment
+func (s *CalibrationService) GetEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.Equipment, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindEquipment(ctx, orgID, id)
+}
+
+func validateEquipment(request types.EquipmentRequest) (types.Equipment, error) {
+	equipment := types.Equipment{
+		Code:                    strings.TrimSpace(request.Code),
+		Name:                    strings.TrimSpace(request.Name),
+		EquipmentType:           strings.TrimSpace(request.EquipmentType),
+		Manufacturer:            strings.TrimSpace(request.Manufacturer),
+		Model:                   strings.TrimSpace(request.Model),
+		SerialNumber:            strings.TrimSpace(request.SerialNumber),
+		Location:                strings.TrimSpace(request.Location),
+		AssetID:                 request.AssetID,
+		CalibrationIntervalDays: request.CalibrationIntervalDays,
+		AlertDays:               DefaultAlertDays,
+		Status:                  request.Status,
+		Notes:                   strings.TrimSpace(request.Notes),
+	}
+	if equipment.Code == "" || equipment.Name == "" {
+		return equipment, fmt.Errorf("%w: code and name are required", ErrInvalid)
+	}
+	if equipment.CalibrationIntervalDays <= 0 || equipment.CalibrationIntervalDays > MaxIntervalDays {
+		return equipment, fmt.Errorf("%w: calibration_interval_days must be between 1 and %d", ErrInvalid, MaxIntervalDays)
+	}
+	if request.AlertDays != nil {
+		if *request.AlertDays < 0 || *request.AlertDays > MaxAlertDays {
+			return equipment, fmt.Errorf("%w: alert_days must be between 0 and %d", ErrInvalid, MaxAlertDays)
+		}
+		equipment.AlertDays = *request.AlertDays
+	}
+	if equipment.Status != "" && !equipment.Status.IsValid() {
+		return equipment, fmt.Errorf("%w: unknown status %q", ErrInvalid, equipment.Status)
+	}
+	return equipment, nil
+}
+
+// CreateEquipment validates and adds equipment. It cannot be used for
+// measurements until its first calibration is recorded.
+func (s *CalibrationService) CreateEquipment(ctx context.Context, orgID, userID uuid.UUID, request types.EquipmentRequest) (*types.Equipment, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	equipment, err := validateEquipment(request)
+	if err != nil {
+		return nil, err
+	}
+	if equipment.Status == "" {
+		equipment.Status = types.EquipmentActive
+	}
+	equipment.OrganizationID = orgID
+	return s.repo.CreateEquipment(ctx, &userID, equipment)
+}
+
+// UpdateEquipment validates and replaces the details of equipment. Without
+// a status it keeps its own; equipment only returns to service by hand while
+// its latest calibration passed and has not lapsed.
+func (s *CalibrationService) UpdateEquipment(ctx context.Context, orgID, userID, id uuid.UUID, request types.EquipmentRequest) (*types.Equipment, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	equipment, err := validateEquipment(request)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindEquipment(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if equipment.Status == "" {
+		equipment.Status = existing.Status
+	}
+	if equipment.Status == types.EquipmentActive && existing.Status != types.EquipmentActive {
+		lapsed := existing.NextDueOn != nil && existing.NextDueOn.Before(s.today())
+		if existing.LastResult == types.ResultFail || lapsed {
+			return nil, fmt.Errorf("%w: equipment %s must pass a calibration to return to service", ErrInvalid, existing.Code)
+		}
+	}
+
+	equipment.OrganizationID = orgID
+	equipment.ID = id
+	return s.repo.UpdateEquipment(ctx, &userID, equipment)
+}
+
+// ListCalibrations returns the calibration history of equipment
+func (s *CalibrationService) ListCalibrations(ctx context.Context, orgID, equipmentID uuid.UUID) ([]types.Calibration, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindEquipment(ctx, orgID, equipmentID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListCalibrations(ctx, orgID, equipmentID)
+}
+
+// RecordCalibration records a calibration of equipment. A passed
+// calibration must reference its certificate and, without a due date,
+// lapses after the equipment's interval. A failed one takes the equipment
+// out of service.
+func (s *CalibrationService) RecordCalibration(ctx context.Context, orgID, userID, equipmentID uuid.UUID, request types.CalibrationRequest) (*types.Calibration, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !request.Result.IsValid() {
+		return nil, fmt.Errorf("%w: result must be pass, adjusted or fail", ErrInvalid)
+	}
+	if request.CalibratedOn.IsZero() {
+		return nil, fmt.Errorf("%w: calibrated_on is required", ErrInvalid)
+	}
+	calibratedOn := request.CalibratedOn.UTC().Truncate(24 * time.Hour)
+	if calibratedOn.After(s.today()) {
+		return nil, fmt.Errorf("%w: calibrated_on cannot be in the future", ErrInvalid)
+	}
+
+	calibration := types.Calibration{
+		OrganizationID:    orgID,
+		EquipmentID:       equipmentID,
+		CalibratedOn:      calibratedOn,
+		Result:            request.Result,
+		PerformedBy:       strings.TrimSpace(request.PerformedBy),
+		CertificateNumber: strings.TrimSpace(request.CertificateNumber),
+		CertificateURL:    strings.TrimSpace(request.CertificateURL),
+		Notes:             strings.TrimSpace(request.Notes),
+	}
+
+	if request.Result.Passed() {
+		if calibration.CertificateNumber == "" && calibration.CertificateURL == "" {
+			return nil, fmt.Errorf("%w: a passed calibration requires a certificate_number or certificate_url", ErrInvalid)
+		}
+		equipment, err := s.repo.FindEquipment(ctx, orgID, equipmentID)
+		if err != nil {
+			return nil, err
+		}
+		nextDueOn := calibratedOn.AddDate(0, 0, equipment.CalibrationIntervalDays)
+		if request.NextDueOn != nil {
+			nextDueOn = request.NextDueOn.UTC().Truncate(24 * time.Hour)
+			if !nextDueOn.After(calibratedOn) {
+				return nil, fmt.Errorf("%w: next_due_on must be after calibrated_on", ErrInvalid)
+			}
+		}
+		calibration.NextDueOn = &nextDueOn
+	}
+
+	created, equipment, err := s.repo.RecordCalibration(ctx, &userID, calibration)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, EventCalibrationRecorded, created)
+	if !created.Result.Passed() && equipment.Status == types.EquipmentOutOfService {
+		s.publishEvent(ctx, EventCalibrationFailed, equipment)
+	}
+	return created, nil
+}
+
+// ListUsage returns the inspection items measured with equipment, on or
+// after since when set
+func (s *CalibrationService) ListUsage(ctx context.Context, orgID, equipmentID uuid.UUID, since *time.Time) ([]types.Usage, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindEquipment(ctx, orgID, equipmentID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListUsage(ctx, orgID, equipmentID, since)
+}
+
+// RequireCalibrated fails with ErrNotCalibrated unless the equipment is in
+// service and within its calibration due date today. It is called by other
+// modules when recording measurements and checks no permission.
+func (s *CalibrationService) RequireCalibrated(ctx context.Context, orgID, equipmentID uuid.UUID) error {
+	equipment, err := s.repo.FindEquipment(ctx, orgID, equipmentID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case equipment.CalibratedOn(s.today()):
+		return nil
+	case equipment.Status != types.EquipmentActive:
+		return fmt.Errorf("%w: equipment %s is %s", ErrNotCalibrated, equipment.Code, equipment.Status)
+	case equipment.NextDueOn == nil:
+		return fmt.Errorf("%w: equipment %s has never been calibrated", ErrNotCalibrated, equipment.Code)
+	}
+	return fmt.Errorf("%w: equipment %s was due for calibration on %s",
+		ErrNotCalibrated, equipment.Code, equipment.NextDueOn.Format("2006-01-02"))
+}
+
+// CheckDue raises the due alerts of the organization's equipment that
+// entered its alert window since the last check
+func (s *CalibrationService) CheckDue(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	if err := s.authService.CheckPermission(ctx, "calibration:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.checkDue(ctx, orgID)
+}
+
+// CheckAll checks the equipment of every organization. It is called by the
+// scheduler, outside of any user session.
+func (s *CalibrationService) CheckAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		result, err := s.checkDue(ctx, orgID)
+		if err != nil {
+			failed++
+			s.logger.Error("Calibration due check failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if len(result.Alerts) > 0 {
+			s.logger.Info("Equipment due for calibration", "organization_id", orgID, "alerts", len(result.Alerts))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("calibration due check failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *CalibrationService) checkDue(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	alerts, err := s.repo.ClaimDueAlerts(ctx, orgID, s.today())
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		s.publishEvent(ctx, EventCalibrationDue, alert)
+	}
+	return &types.CheckResult{Alerts: alerts}, nil
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/calibration/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCalibrationRepo struct {
+	equipment    []types.Equipment
+	calibrations []types.Calibration
+}
+
+func (f *fakeCalibrationRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeCalibrationRepo) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.Equipment, error) {
+	return f.equipment, nil
+}
+
+func (f *fakeCalibrationRepo) FindEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.Equipment, error) {
+	for i := range f.equipment {
+		if f.equipment[i].ID == id {
+			return &f.equipment[i], nil
+		}
+	}
+	return nil, fmt.Errorf("equipment %w", repository.ErrNotFound)
+}
+
+func (f *fakeCalibrationRepo) CreateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error) {
+	equipment.ID = uuid.New()
+	f.equipment = append(f.equipment, equipment)
+	return &equipment, nil
+}
+
+func (f *fakeCalibrationRepo) UpdateEquipment(ctx context.Context, userID *uuid.UUID, equipment types.Equipment) (*types.Equipment, error) {
+	existing, err := f.FindEquipment(ctx, equipment.OrganizationID, equipment.ID)
+	if err != nil {
+		return nil, err
+	}
+	existing.Status = equipment.Status
+	return existing, nil
+}
+
+func (f *fakeCalibrationRepo) ListCalibrations(ctx context.Context, orgID, equipmentID uuid.UUID) ([]types.Calibration, error) {
+	return f.calibrations, nil
+}
+
+// RecordCalibration applies the calibration to its equipment as the
+// repository does for the latest calibration
+func (f *fakeCalibrationRepo) RecordCalibration(ctx context.Context, userID *uuid.UUID, calibration types.Calibration) (*types.Calibration, *types.Equipment, error) {
+	equipment, err := f.FindEquipment(ctx, calibration.OrganizationID, calibration.EquipmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	calibration.ID = uuid.New()
+	f.calibrations = append(f.calibrations, calibration)
+
+	equipment.LastCalibratedOn = &calibration.CalibratedOn
+	equipment.LastResult = calibration.Result
+	if calibration.Result.Passed() {
+		equipment.Status = types.EquipmentActive
+		equipment.NextDueOn = calibration.NextDueOn
+	} else {
+		equipment.Status = types.EquipmentOutOfService
+	}
+	return &calibration, equipment, nil
+}
+
+func (f *fakeCalibrationRepo) ListUsage(ctx context.Context, orgID, equipmentID uuid.UUID, since *time.Time) ([]types.Usage, error) {
+	return nil, nil
+}
+
+func (f *fakeCalibrationRepo) ClaimDueAlerts(ctx context.Context, orgID uuid.UUID, day time.Time) ([]types.DueAlert, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeCalibrationRepo) *CalibrationService {
+	svc := NewCalibrationService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 6, 15, 9, 30, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// caliper is an active caliper calibrated every 180 days, never calibrated
+func caliper(repo *fakeCalibrationRepo) types.Equipment {
+	equipment := types.Equipment{ID: uuid.New(), Code: "CAL-01", Name: "Digital caliper",
+		CalibrationIntervalDays: 180, AlertDays: 14, Status: types.EquipmentActive}
+	repo.equipment = append(repo.equipment, equipment)
+	return equipment
+}
+
+func TestRecordCalibrationLapsesAfterInterval(t *testing.T) {
+	repo := &fakeCalibrationRepo{}
+	equipment := caliper(repo)
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	_, err := svc.RecordCalibration(ctx, uuid.New(), uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 1), Result: types.ResultPass,
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "certificate required")
+
+	_, err = svc.RecordCalibration(ctx, uuid.New(), uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 16), Result: types.ResultPass, CertificateNumber: "C-1",
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "calibrated_on in the future")
+
+	_, err = svc.RecordCalibration(ctx, uuid.New(), uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 1), Result: types.ResultPass, CertificateNumber: "C-1", NextDueOn: ptr(day(6, 1)),
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "next_due_on not after calibrated_on")
+
+	calibration, err := svc.RecordCalibration(ctx, uuid.New(), uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 1), Result: types.ResultPass, CertificateNumber: "C-1",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, calibration.NextDueOn)
+	assert.Equal(t, day(11, 28), *calibration.NextDueOn)
+
+	// A failed calibration needs no certificate and has no due date
+	calibration, err = svc.RecordCalibration(ctx, uuid.New(), uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 10), Result: types.ResultFail,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, calibration.NextDueOn)
+}
+
+func TestEquipmentCalibratedOn(t *testing.T) {
+	today := day(6, 15)
+	equipment := types.Equipment{Status: types.EquipmentActive}
+
+	assert.False(t, equipment.CalibratedOn(today), "never calibrated")
+	equipment.NextDueOn = ptr(day(6, 15))
+	assert.True(t, equipment.CalibratedOn(today), "usable through its due date")
+	equipment.NextDueOn = ptr(day(6, 14))
+	assert.False(t, equipment.CalibratedOn(today))
+
+	equipment.NextDueOn = ptr(day(12, 1))
+	equipment.Status = types.EquipmentOutOfService
+	assert.False(t, equipment.CalibratedOn(today))
+}
+
+func TestRequireCalibrated(t *testing.T) {
+	repo := &fakeCalibrationRepo{}
+	equipment := caliper(repo)
+	svc := newTestService(repo)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	err := svc.RequireCalibrated(ctx, orgID, equipment.ID)
+	assert.ErrorIs(t, err, ErrNotCalibrated)
+	assert.Contains(t, err.Error(), "never been calibrated")
+
+	_, err = svc.RecordCalibration(ctx, orgID, uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 1), Result: types.ResultAdjusted, CertificateURL: "https://certs.example.com/C-1.pdf",
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.RequireCalibrated(ctx, orgID, equipment.ID))
+
+	// Lapsed equipment cannot be used until it is calibrated again
+	svc.now = func() time.Time { return day(11, 29) }
+	err = svc.RequireCalibrated(ctx, orgID, equipment.ID)
+	assert.ErrorIs(t, err, ErrNotCalibrated)
+	assert.Contains(t, err.Error(), "2025-11-28")
+
+	assert.ErrorIs(t, svc.RequireCalibrated(ctx, orgID, uuid.New()), repository.ErrNotFound)
+}
+
+func TestFailedCalibrationTakesEquipmentOutOfService(t *testing.T) {
+	repo := &fakeCalibrationRepo{}
+	equipment := caliper(repo)
+	svc := newTestService(repo)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	_, err := svc.RecordCalibration(ctx, orgID, uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 1), Result: types.ResultPass, CertificateNumber: "C-1",
+	})
+	require.NoError(t, err)
+	_, err = svc.RecordCalibration(ctx, orgID, uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 14), Result: types.ResultFail, Notes: "Jaw wear beyond tolerance",
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.RequireCalibrated(ctx, orgID, equipment.ID), ErrNotCalibrated)
+
+	// It only returns to service by passing a calibration
+	request := types.EquipmentRequest{Code: "CAL-01", Name: "Digital caliper",
+		CalibrationIntervalDays: 180, Status: types.EquipmentActive}
+	_, err = svc.UpdateEquipment(ctx, orgID, uuid.New(), equipment.ID, request)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.RecordCalibration(ctx, orgID, uuid.New(), equipment.ID, types.CalibrationRequest{
+		CalibratedOn: day(6, 15), Result: types.ResultAdjusted, CertificateNumber: "C-2",
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.RequireCalibrated(ctx, orgID, equipment.ID))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
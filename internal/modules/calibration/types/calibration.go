@@ -0,0 +1,161 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EquipmentStatus is whether equipment can be used for measurements
+type EquipmentStatus string
+
+const (
+	EquipmentActive EquipmentStatus = "active"
+	// EquipmentOutOfService is set by a failed calibration, or by hand for
+	// repairs, until the equipment passes a calibration again
+	EquipmentOutOfService EquipmentStatus = "out_of_service"
+	EquipmentRetired      EquipmentStatus = "retired"
+)
+
+// IsValid reports whether the status is known
+func (s EquipmentStatus) IsValid() bool {
+	return s == EquipmentActive || s == EquipmentOutOfService || s == EquipmentRetired
+}
+
+// Equipment is a measuring instrument or tool that must be calibrated on a
+// fixed interval
+type Equipment struct {
+	ID                      uuid.UUID         `json:"id"`
+	OrganizationID          uuid.UUID         `json:"organization_id"`
+	Code                    string            `json:"code"`
+	Name                    string            `json:"name"`
+	EquipmentType           string            `json:"equipment_type,omitempty"`
+	Manufacturer            string            `json:"manufacturer,omitempty"`
+	Model                   string            `json:"model,omitempty"`
+	SerialNumber            string            `json:"serial_number,omitempty"`
+	Location                string            `json:"location,omitempty"`
+	AssetID                 *uuid.UUID        `json:"asset_id,omitempty"`
+	CalibrationIntervalDays int               `json:"calibration_interval_days"`
+	AlertDays               int               `json:"alert_days"`
+	Status                  EquipmentStatus   `json:"status"`
+	LastCalibratedOn        *time.Time        `json:"last_calibrated_on,omitempty"`
+	LastResult              CalibrationResult `json:"last_result,omitempty"`
+	// NextDueOn is the last day the equipment may be used before it is
+	// calibrated again; nil until it is first calibrated
+	NextDueOn    *time.Time `json:"next_due_on,omitempty"`
+	AlertedDueOn *time.Time `json:"alerted_due_on,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CalibratedOn reports whether the equipment may be used for measurements
+// on the day
+func (e Equipment) CalibratedOn(day time.Time) bool {
+	return e.Status == EquipmentActive && e.NextDueOn != nil && !e.NextDueOn.Before(day)
+}
+
+// EquipmentRequest creates or replaces equipment. Its status and
+// calibration dates follow its calibrations; Status only takes equipment
+// out of service, retires it, or returns it to service while calibrated.
+type EquipmentRequest struct {
+	Code                    string          `json:"code"`
+	Name                    string          `json:"name"`
+	EquipmentType           string          `json:"equipment_type"`
+	Manufacturer            string          `json:"manufacturer"`
+	Model                   string          `json:"model"`
+	SerialNumber            string          `json:"serial_number"`
+	Location                string          `json:"location"`
+	AssetID                 *uuid.UUID      `json:"asset_id,omitempty"`
+	CalibrationIntervalDays int             `json:"calibration_interval_days"`
+	AlertDays               *int            `json:"alert_days,omitempty"`
+	Status                  EquipmentStatus `json:"status,omitempty"`
+	Notes                   string          `json:"notes"`
+}
+
+// EquipmentFilter selects equipment
+type EquipmentFilter struct {
+	OrganizationID uuid.UUID
+	Status         EquipmentStatus
+	EquipmentType  string
+	// DueBefore selects active equipment due on or before the day, or
+	// never calibrated
+	DueBefore *time.Time
+}
+
+// CalibrationResult is the outcome of a calibration
+type CalibrationResult string
+
+const (
+	ResultPass CalibrationResult = "pass"
+	// ResultAdjusted passed after the equipment was adjusted
+	ResultAdjusted CalibrationResult = "adjusted"
+	ResultFail     CalibrationResult = "fail"
+)
+
+// IsValid reports whether the result is known
+func (r CalibrationResult) IsValid() bool {
+	return r == ResultPass || r == ResultAdjusted || r == ResultFail
+}
+
+// Passed reports whether the equipment can be used after the calibration
+func (r CalibrationResult) Passed() bool {
+	return r == ResultPass || r == ResultAdjusted
+}
+
+// Calibration is a calibration performed on equipment
+type Calibration struct {
+	ID                uuid.UUID         `json:"id"`
+	OrganizationID    uuid.UUID         `json:"organization_id"`
+	EquipmentID       uuid.UUID         `json:"equipment_id"`
+	CalibratedOn      time.Time         `json:"calibrated_on"`
+	Result            CalibrationResult `json:"result"`
+	PerformedBy       string            `json:"performed_by,omitempty"`
+	CertificateNumber string            `json:"certificate_number,omitempty"`
+	CertificateURL    string            `json:"certificate_url,omitempty"`
+	// NextDueOn is when the calibration lapses; nil for failed calibrations
+	NextDueOn *time.Time `json:"next_due_on,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+}
+
+// CalibrationRequest records a calibration. Without a due date, a passed
+// calibration lapses after the equipment's interval.
+type CalibrationRequest struct {
+	CalibratedOn      time.Time         `json:"calibrated_on"`
+	Result            CalibrationResult `json:"result"`
+	PerformedBy       string            `json:"performed_by"`
+	CertificateNumber string            `json:"certificate_number"`
+	CertificateURL    string            `json:"certificate_url"`
+	NextDueOn         *time.Time        `json:"next_due_on,omitempty"`
+	Notes             string            `json:"notes"`
+}
+
+// Usage is an inspection item measured with equipment
+type Usage struct {
+	InspectionItemID uuid.UUID `json:"inspection_item_id"`
+	InspectionID     uuid.UUID `json:"inspection_id"`
+	ProductID        uuid.UUID `json:"product_id"`
+	ProductName      string    `json:"product_name"`
+	Description      string    `json:"description"`
+	Result           string    `json:"result"`
+	MeasuredAt       time.Time `json:"measured_at"`
+}
+
+// DueAlert is raised once for each due date equipment reaches the alert
+// window of
+type DueAlert struct {
+	EquipmentID    uuid.UUID `json:"equipment_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Code           string    `json:"code"`
+	Name           string    `json:"name"`
+	Location       string    `json:"location,omitempty"`
+	DueOn          time.Time `json:"due_on"`
+	Overdue        bool      `json:"overdue"`
+}
+
+// CheckResult is the outcome of a due check
+type CheckResult struct {
+	Alerts []DueAlert `json:"alerts"`
+}
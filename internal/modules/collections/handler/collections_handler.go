@@ -0,0 +1,446 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CollectionsHandler handles dunning levels and notices, promises to pay,
+// agent work queues and aging analytics
+type CollectionsHandler struct {
+	service *service.CollectionsService
+}
+
+func NewCollectionsHandler(service *service.CollectionsService) *CollectionsHandler {
+	return &CollectionsHandler{service: service}
+}
+
+func (h *CollectionsHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/collections/levels", h.ListLevels)
+	router.POST("/api/collections/levels", h.CreateLevel)
+	router.GET("/api/collections/levels/:id", h.GetLevel)
+	router.PUT("/api/collections/levels/:id", h.UpdateLevel)
+	router.DELETE("/api/collections/levels/:id", h.DeleteLevel)
+
+	router.POST("/api/collections/run", h.Run)
+	router.GET("/api/collections/notices", h.ListNotices)
+	router.GET("/api/collections/queue", h.Queue)
+
+	router.GET("/api/collections/promises", h.ListPromises)
+	router.POST("/api/collections/promises", h.CreatePromise)
+	router.POST("/api/collections/promises/:id/cancel", h.CancelPromise)
+
+	router.GET("/api/collections/assignments", h.ListAssignments)
+	router.PUT("/api/collections/assignments", h.Assign)
+	router.DELETE("/api/collections/assignments/:partner_id", h.Unassign)
+
+	router.GET("/api/collections/aging", h.Aging)
+}
+
+// ListLevels handles GET /api/collections/levels
+func (h *CollectionsHandler) ListLevels(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	levels, err := h.service.ListLevels(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, levels)
+}
+
+// CreateLevel handles POST /api/collections/levels
+func (h *CollectionsHandler) CreateLevel(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.DunningLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.service.CreateLevel(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, level)
+}
+
+// GetLevel handles GET /api/collections/levels/:id
+func (h *CollectionsHandler) GetLevel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid level ID", http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.service.GetLevel(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, level)
+}
+
+// UpdateLevel handles PUT /api/collections/levels/:id
+func (h *CollectionsHandler) UpdateLevel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid level ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DunningLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level, err := h.service.UpdateLevel(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, level)
+}
+
+// DeleteLevel handles DELETE /api/collections/levels/:id
+func (h *CollectionsHandler) DeleteLevel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid level ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteLevel(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run handles POST /api/collections/run, sending due notices without
+// waiting for the scheduled run
+func (h *CollectionsHandler) Run(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Run(r.Context(), authCtx.OrganizationID, authCtx.UserID)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ListNotices handles GET /api/collections/notices with optional
+// invoice_id, status, limit and offset filters
+func (h *CollectionsHandler) ListNotices(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.NoticeFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.NoticeStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("invoice_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid invoice_id", http.StatusBadRequest)
+			return
+		}
+		filter.InvoiceID = &id
+	}
+
+	notices, err := h.service.ListNotices(r.Context(), filter)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notices)
+}
+
+// Queue handles GET /api/collections/queue, the work queue of the calling
+// agent. agent_id shows another agent's queue and unassigned=true the
+// overdue invoices of customers without an agent.
+func (h *CollectionsHandler) Queue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.QueueFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Unassigned:     q.Get("unassigned") == "true",
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("agent_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid agent_id", http.StatusBadRequest)
+			return
+		}
+		filter.AgentID = &id
+	} else if !filter.Unassigned {
+		filter.AgentID = &authCtx.UserID
+	}
+
+	invoices, err := h.service.Queue(r.Context(), filter)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invoices)
+}
+
+// ListPromises handles GET /api/collections/promises with optional
+// invoice_id, status, limit and offset filters
+func (h *CollectionsHandler) ListPromises(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.PromiseFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.PromiseStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("invoice_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid invoice_id", http.StatusBadRequest)
+			return
+		}
+		filter.InvoiceID = &id
+	}
+
+	promises, err := h.service.ListPromises(r.Context(), filter)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promises)
+}
+
+// CreatePromise handles POST /api/collections/promises
+func (h *CollectionsHandler) CreatePromise(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PromiseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promise, err := h.service.CreatePromise(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, promise)
+}
+
+// CancelPromise handles POST /api/collections/promises/:id/cancel
+func (h *CollectionsHandler) CancelPromise(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid promise ID", http.StatusBadRequest)
+		return
+	}
+
+	promise, err := h.service.CancelPromise(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promise)
+}
+
+// ListAssignments handles GET /api/collections/assignments with an
+// optional agent_id filter
+func (h *CollectionsHandler) ListAssignments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var agentID *uuid.UUID
+	if v := r.URL.Query().Get("agent_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid agent_id", http.StatusBadRequest)
+			return
+		}
+		agentID = &id
+	}
+
+	assignments, err := h.service.ListAssignments(r.Context(), authCtx.OrganizationID, agentID)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, assignments)
+}
+
+// Assign handles PUT /api/collections/assignments, moving customers to an
+// agent's work queue
+func (h *CollectionsHandler) Assign(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.AssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assignments, err := h.service.Assign(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, assignments)
+}
+
+// Unassign handles DELETE /api/collections/assignments/:partner_id
+func (h *CollectionsHandler) Unassign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	partnerID, err := uuid.Parse(ps.ByName("partner_id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Unassign(r.Context(), authCtx.OrganizationID, partnerID); err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Aging handles GET /api/collections/aging with optional from and to dates
+func (h *CollectionsHandler) Aging(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var from, to *time.Time
+	q := r.URL.Query()
+	for name, dest := range map[string]**time.Time{"from": &from, "to": &to} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	report, err := h.service.Aging(r.Context(), authCtx.OrganizationID, from, to)
+	if err != nil {
+		writeCollectionsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCollectionsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicateLevel), errors.Is(err, repository.ErrPromiseOpen),
+		errors.Is(err, service.ErrPromiseClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeCollectionsDunning = "collections.dunning"
+
+// DunningJobHandler handles queued dunning jobs
+type DunningJobHandler struct {
+	collectionsService *service.CollectionsService
+}
+
+func NewDunningJobHandler(collectionsService *service.CollectionsService) *DunningJobHandler {
+	return &DunningJobHandler{
+		collectionsService: collectionsService,
+	}
+}
+
+// Handle processes a dunning job
+func (h *DunningJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.collectionsService.RunAll(ctx); err != nil {
+		return fmt.Errorf("failed to run dunning: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DunningJobHandler) JobType() string {
+	return JobTypeCollectionsDunning
+}
+
+// Scheduler runs dunning on a fixed interval, so overdue invoices get their
+// notices and each day's aging is recorded without anyone asking
+type Scheduler struct {
+	handler     *DunningJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DunningJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs dunning every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeCollectionsDunning, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeCollectionsDunning, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled dunning run failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule dunning run", "error", err)
+	}
+}
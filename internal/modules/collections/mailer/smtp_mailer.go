@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/KevTiv/alieze-erp/pkg/email"
+)
+
+// SMTPMailer sends dunning notices as plain text email through the shared
+// email service
+type SMTPMailer struct {
+	service email.Service
+}
+
+func NewSMTPMailer(config *email.SMTPConfig, from string) (*SMTPMailer, error) {
+	service, err := email.NewSMTPService(config, from)
+	if err != nil {
+		return nil, err
+	}
+	return &SMTPMailer{service: service}, nil
+}
+
+// Send emails a rendered dunning notice
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	return m.service.Send(ctx, &email.Email{
+		To:      []string{to},
+		Subject: subject,
+		Body:    body,
+	})
+}
@@ -0,0 +1,91 @@
+package collections
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CollectionsModule represents the dunning and collections module
+type CollectionsModule struct {
+	collectionsService *service.CollectionsService
+	collectionsHandler *handler.CollectionsHandler
+	scheduler          *jobs.Scheduler
+	logger             *slog.Logger
+}
+
+// NewCollectionsModule creates a new collections module
+func NewCollectionsModule() *CollectionsModule {
+	return &CollectionsModule{}
+}
+
+// Name returns the module name
+func (m *CollectionsModule) Name() string {
+	return "collections"
+}
+
+// SetMailer emails dunning notices to customers. It must be called after Init.
+func (m *CollectionsModule) SetMailer(mailer service.Mailer) {
+	if m.collectionsService != nil {
+		m.collectionsService.SetMailer(mailer)
+	}
+}
+
+// SetConsentChecker skips the dunning notices of customers who opted out
+// of email. It must be called after Init.
+func (m *CollectionsModule) SetConsentChecker(consents service.ConsentChecker) {
+	if m.collectionsService != nil {
+		m.collectionsService.SetConsentChecker(consents)
+	}
+}
+
+// Init initializes the collections module and starts scheduled dunning
+func (m *CollectionsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "collections")
+	m.logger.Info("Initializing collections module")
+
+	// Create repositories
+	collectionsRepo := repository.NewCollectionsRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.collectionsService = service.NewCollectionsService(collectionsRepo, authAdapter, m.logger)
+
+	// Create scheduled dunning
+	dunningJobHandler := jobs.NewDunningJobHandler(m.collectionsService)
+	m.scheduler = jobs.NewScheduler(dunningJobHandler, service.RunInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.collectionsHandler = handler.NewCollectionsHandler(m.collectionsService)
+
+	m.logger.Info("Collections module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers collections module routes
+func (m *CollectionsModule) RegisterRoutes(router interface{}) {
+	if m.collectionsHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.collectionsHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the collections module
+func (m *CollectionsModule) RegisterEventHandlers(bus interface{}) {
+	// Dunning is schedule driven, reading overdue invoices directly
+}
+
+// Health checks the health of the collections module
+func (m *CollectionsModule) Health() error {
+	return nil
+}
@@ -0,0 +1,670 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a level, invoice, promise or customer does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateLevel is returned when another dunning level has the number
+	ErrDuplicateLevel = errors.New("dunning level already exists")
+	// ErrPromiseOpen is returned when the invoice already has an open promise to pay
+	ErrPromiseOpen = errors.New("invoice already has an open promise to pay")
+)
+
+// CollectionsRepo defines the interface for collections repository operations
+type CollectionsRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListLevels(ctx context.Context, orgID uuid.UUID) ([]types.DunningLevel, error)
+	FindLevel(ctx context.Context, orgID, id uuid.UUID) (*types.DunningLevel, error)
+	CreateLevel(ctx context.Context, orgID, userID uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error)
+	UpdateLevel(ctx context.Context, orgID, userID, id uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error)
+	DeleteLevel(ctx context.Context, orgID, id uuid.UUID) error
+	FindInvoice(ctx context.Context, orgID, invoiceID uuid.UUID, asOf time.Time) (*types.OpenInvoice, error)
+	OverdueInvoices(ctx context.Context, filter types.QueueFilter) ([]types.OpenInvoice, error)
+	SaveNotice(ctx context.Context, notice types.Notice) (*types.Notice, error)
+	ListNotices(ctx context.Context, filter types.NoticeFilter) ([]types.Notice, error)
+	OpenPromises(ctx context.Context, orgID uuid.UUID) ([]types.PromiseCheck, error)
+	CreatePromise(ctx context.Context, promise types.Promise) (*types.Promise, error)
+	FindPromise(ctx context.Context, orgID, id uuid.UUID) (*types.Promise, error)
+	ListPromises(ctx context.Context, filter types.PromiseFilter) ([]types.Promise, error)
+	ResolvePromise(ctx context.Context, orgID, id uuid.UUID, status types.PromiseStatus, at time.Time) error
+	ListAssignments(ctx context.Context, orgID uuid.UUID, agentID *uuid.UUID) ([]types.Assignment, error)
+	Assign(ctx context.Context, orgID, userID uuid.UUID, request types.AssignmentRequest) error
+	Unassign(ctx context.Context, orgID, partnerID uuid.UUID) error
+	Aging(ctx context.Context, orgID uuid.UUID, asOf time.Time) (*types.AgingSnapshot, error)
+	SaveSnapshot(ctx context.Context, orgID uuid.UUID, snapshot types.AgingSnapshot) error
+	ListSnapshots(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.AgingSnapshot, error)
+}
+
+// CollectionsRepository stores dunning levels, notices, promises to pay,
+// agent assignments and aging snapshots, and reads open customer invoices
+type CollectionsRepository struct {
+	db *sql.DB
+}
+
+// Ensure CollectionsRepository implements CollectionsRepo interface
+var _ CollectionsRepo = &CollectionsRepository{}
+
+func NewCollectionsRepository(db *sql.DB) *CollectionsRepository {
+	return &CollectionsRepository{db: db}
+}
+
+// openInvoice restricts invoices i to posted customer invoices with an
+// amount still due
+const openInvoice = `
+	i.move_type = 'out_invoice' AND i.state = 'posted' AND i.payment_state IN ('not_paid', 'partial')
+	AND i.amount_residual > 0 AND i.deleted_at IS NULL
+`
+
+// dueDate is the due date of invoice i, falling back to its invoice date
+const dueDate = `COALESCE(i.invoice_date_due, i.invoice_date, i.date)`
+
+// ListOrganizationIDs returns the organizations with open customer invoices
+func (r *CollectionsRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT i.organization_id FROM invoices i WHERE `+openInvoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const levelColumns = `
+	id, organization_id, level, name, days_overdue, fee_fixed, fee_percent, email_subject, email_body,
+	active, created_at, updated_at
+`
+
+func scanLevel(row interface{ Scan(...interface{}) error }) (*types.DunningLevel, error) {
+	var l types.DunningLevel
+	err := row.Scan(&l.ID, &l.OrganizationID, &l.Level, &l.Name, &l.DaysOverdue, &l.FeeFixed, &l.FeePercent,
+		&l.EmailSubject, &l.EmailBody, &l.Active, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *CollectionsRepository) ListLevels(ctx context.Context, orgID uuid.UUID) ([]types.DunningLevel, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+levelColumns+` FROM dunning_levels WHERE organization_id = $1 ORDER BY level
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dunning levels: %w", err)
+	}
+	defer rows.Close()
+
+	levels := []types.DunningLevel{}
+	for rows.Next() {
+		l, err := scanLevel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dunning level: %w", err)
+		}
+		levels = append(levels, *l)
+	}
+	return levels, rows.Err()
+}
+
+func (r *CollectionsRepository) FindLevel(ctx context.Context, orgID, id uuid.UUID) (*types.DunningLevel, error) {
+	l, err := scanLevel(r.db.QueryRowContext(ctx, `
+		SELECT `+levelColumns+` FROM dunning_levels WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("dunning level %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find dunning level: %w", err)
+	}
+	return l, nil
+}
+
+func (r *CollectionsRepository) CreateLevel(ctx context.Context, orgID, userID uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	l, err := scanLevel(r.db.QueryRowContext(ctx, `
+		INSERT INTO dunning_levels (
+			organization_id, level, name, days_overdue, fee_fixed, fee_percent, email_subject, email_body,
+			active, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		RETURNING `+levelColumns,
+		orgID, request.Level, request.Name, request.DaysOverdue, request.FeeFixed, request.FeePercent,
+		request.EmailSubject, request.EmailBody, request.Active == nil || *request.Active, userID))
+	if err != nil {
+		return nil, levelError(err, request.Level)
+	}
+	return l, nil
+}
+
+func (r *CollectionsRepository) UpdateLevel(ctx context.Context, orgID, userID, id uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	l, err := scanLevel(r.db.QueryRowContext(ctx, `
+		UPDATE dunning_levels SET
+			level = $3, name = $4, days_overdue = $5, fee_fixed = $6, fee_percent = $7,
+			email_subject = $8, email_body = $9, active = $10, updated_by = $11, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+levelColumns,
+		orgID, id, request.Level, request.Name, request.DaysOverdue, request.FeeFixed, request.FeePercent,
+		request.EmailSubject, request.EmailBody, request.Active == nil || *request.Active, userID))
+	if err != nil {
+		return nil, levelError(err, request.Level)
+	}
+	return l, nil
+}
+
+func levelError(err error, level int) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("dunning level %w", ErrNotFound)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: level %d", ErrDuplicateLevel, level)
+	}
+	return fmt.Errorf("failed to save dunning level: %w", err)
+}
+
+// DeleteLevel removes a dunning level. Notices sent at the level keep its number.
+func (r *CollectionsRepository) DeleteLevel(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM dunning_levels WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dunning level: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("dunning level %w", ErrNotFound)
+	}
+	return nil
+}
+
+// openInvoiceQuery selects open invoices of organization $1 as of date $2,
+// with their dunning progress, agent and open promise
+const openInvoiceQuery = `
+	SELECT i.id, COALESCE(i.name, ''), i.partner_id, COALESCE(c.display_name, c.name), COALESCE(c.email, ''),
+		` + dueDate + `, GREATEST($2::date - ` + dueDate + `, 0), i.amount_total, i.amount_residual,
+		COALESCE(n.last_level, 0), n.last_notice_at, COALESCE(n.fees, 0), a.agent_id,
+		p.id, p.promised_amount, p.promised_date, p.amount_due_at_promise, p.note, p.created_at, p.created_by
+	FROM invoices i
+	JOIN contacts c ON c.id = i.partner_id
+	LEFT JOIN LATERAL (
+		SELECT MAX(level) AS last_level, MAX(sent_at) AS last_notice_at, SUM(fee) AS fees
+		FROM dunning_notices WHERE invoice_id = i.id AND status <> 'failed'
+	) n ON true
+	LEFT JOIN collection_assignments a ON a.organization_id = i.organization_id AND a.partner_id = i.partner_id
+	LEFT JOIN collection_promises p ON p.invoice_id = i.id AND p.status = 'open'
+	WHERE i.organization_id = $1 AND ` + openInvoice
+
+func scanOpenInvoice(row interface{ Scan(...interface{}) error }) (*types.OpenInvoice, error) {
+	var inv types.OpenInvoice
+	var lastNoticeAt sql.NullTime
+	var agentID, promiseID, promiseCreatedBy uuid.NullUUID
+	var promisedAmount, amountDueAtPromise sql.NullFloat64
+	var promisedDate, promiseCreatedAt sql.NullTime
+	var promiseNote sql.NullString
+	err := row.Scan(&inv.InvoiceID, &inv.InvoiceName, &inv.PartnerID, &inv.PartnerName, &inv.PartnerEmail,
+		&inv.DueDate, &inv.DaysOverdue, &inv.AmountTotal, &inv.AmountDue,
+		&inv.LastLevel, &lastNoticeAt, &inv.Fees, &agentID,
+		&promiseID, &promisedAmount, &promisedDate, &amountDueAtPromise, &promiseNote, &promiseCreatedAt, &promiseCreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if lastNoticeAt.Valid {
+		inv.LastNoticeAt = &lastNoticeAt.Time
+	}
+	if agentID.Valid {
+		inv.AgentID = &agentID.UUID
+	}
+	if promiseID.Valid {
+		inv.Promise = &types.Promise{
+			ID:                 promiseID.UUID,
+			InvoiceID:          inv.InvoiceID,
+			PromisedAmount:     promisedAmount.Float64,
+			PromisedDate:       promisedDate.Time,
+			AmountDueAtPromise: amountDueAtPromise.Float64,
+			Status:             types.PromiseOpen,
+			Note:               promiseNote.String,
+			CreatedAt:          promiseCreatedAt.Time,
+		}
+		if promiseCreatedBy.Valid {
+			inv.Promise.CreatedBy = &promiseCreatedBy.UUID
+		}
+	}
+	return &inv, nil
+}
+
+// FindInvoice returns an open customer invoice
+func (r *CollectionsRepository) FindInvoice(ctx context.Context, orgID, invoiceID uuid.UUID, asOf time.Time) (*types.OpenInvoice, error) {
+	inv, err := scanOpenInvoice(r.db.QueryRowContext(ctx, openInvoiceQuery+` AND i.id = $3`, orgID, asOf, invoiceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("open invoice %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find invoice: %w", err)
+	}
+	inv.Promise = withOrganization(inv.Promise, orgID)
+	return inv, nil
+}
+
+// OverdueInvoices returns open invoices past their due date, longest
+// overdue first
+func (r *CollectionsRepository) OverdueInvoices(ctx context.Context, filter types.QueueFilter) ([]types.OpenInvoice, error) {
+	query := openInvoiceQuery + ` AND ` + dueDate + ` < $2::date`
+	args := []interface{}{filter.OrganizationID, filter.AsOf}
+	if filter.AgentID != nil {
+		args = append(args, *filter.AgentID)
+		query += fmt.Sprintf(" AND a.agent_id = $%d", len(args))
+	}
+	if filter.Unassigned {
+		query += " AND a.agent_id IS NULL"
+	}
+	query += " ORDER BY " + dueDate + ", i.amount_residual DESC, i.id"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overdue invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []types.OpenInvoice{}
+	for rows.Next() {
+		inv, err := scanOpenInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan overdue invoice: %w", err)
+		}
+		inv.Promise = withOrganization(inv.Promise, filter.OrganizationID)
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+func withOrganization(promise *types.Promise, orgID uuid.UUID) *types.Promise {
+	if promise != nil {
+		promise.OrganizationID = orgID
+	}
+	return promise
+}
+
+const noticeColumns = `
+	n.id, n.organization_id, n.invoice_id, COALESCE(i.name, ''), n.level_id, n.level, n.days_overdue,
+	n.amount_due, n.fee, COALESCE(n.recipient, ''), n.subject, n.body, n.status, COALESCE(n.error, ''),
+	n.sent_at, n.created_by
+`
+
+func scanNotice(row interface{ Scan(...interface{}) error }) (*types.Notice, error) {
+	var n types.Notice
+	var levelID, createdBy uuid.NullUUID
+	err := row.Scan(&n.ID, &n.OrganizationID, &n.InvoiceID, &n.InvoiceName, &levelID, &n.Level, &n.DaysOverdue,
+		&n.AmountDue, &n.Fee, &n.Recipient, &n.Subject, &n.Body, &n.Status, &n.Error, &n.SentAt, &createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if levelID.Valid {
+		n.LevelID = &levelID.UUID
+	}
+	if createdBy.Valid {
+		n.CreatedBy = &createdBy.UUID
+	}
+	return &n, nil
+}
+
+// SaveNotice records a notice. A notice that failed before at the same
+// level is replaced; it returns nil when the level was already reached.
+func (r *CollectionsRepository) SaveNotice(ctx context.Context, notice types.Notice) (*types.Notice, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO dunning_notices (
+			organization_id, invoice_id, level_id, level, days_overdue, amount_due, fee, recipient,
+			subject, body, status, error, sent_at, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, NULLIF($12, ''), $13, $14)
+		ON CONFLICT (invoice_id, level) DO UPDATE SET
+			level_id = EXCLUDED.level_id, days_overdue = EXCLUDED.days_overdue, amount_due = EXCLUDED.amount_due,
+			fee = EXCLUDED.fee, recipient = EXCLUDED.recipient, subject = EXCLUDED.subject, body = EXCLUDED.body,
+			status = EXCLUDED.status, error = EXCLUDED.error, sent_at = EXCLUDED.sent_at, created_by = EXCLUDED.created_by
+		WHERE dunning_notices.status = 'failed'
+		RETURNING id
+	`, notice.OrganizationID, notice.InvoiceID, notice.LevelID, notice.Level, notice.DaysOverdue, notice.AmountDue,
+		notice.Fee, notice.Recipient, notice.Subject, notice.Body, notice.Status, notice.Error, notice.SentAt,
+		notice.CreatedBy).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to save dunning notice: %w", err)
+	}
+	notice.ID = id
+	return &notice, nil
+}
+
+func (r *CollectionsRepository) ListNotices(ctx context.Context, filter types.NoticeFilter) ([]types.Notice, error) {
+	where := []string{"n.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.InvoiceID != nil {
+		args = append(args, *filter.InvoiceID)
+		where = append(where, fmt.Sprintf("n.invoice_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("n.status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + noticeColumns + ` FROM dunning_notices n JOIN invoices i ON i.id = n.invoice_id WHERE ` +
+		strings.Join(where, " AND ") + ` ORDER BY n.sent_at DESC, n.level DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dunning notices: %w", err)
+	}
+	defer rows.Close()
+
+	notices := []types.Notice{}
+	for rows.Next() {
+		n, err := scanNotice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dunning notice: %w", err)
+		}
+		notices = append(notices, *n)
+	}
+	return notices, rows.Err()
+}
+
+const promiseColumns = `
+	p.id, p.organization_id, p.invoice_id, p.promised_amount, p.promised_date, p.amount_due_at_promise,
+	p.status, COALESCE(p.note, ''), p.resolved_at, p.created_at, p.created_by
+`
+
+func scanPromise(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*types.Promise, error) {
+	var p types.Promise
+	var resolvedAt sql.NullTime
+	var createdBy uuid.NullUUID
+	dest := []interface{}{&p.ID, &p.OrganizationID, &p.InvoiceID, &p.PromisedAmount, &p.PromisedDate,
+		&p.AmountDueAtPromise, &p.Status, &p.Note, &resolvedAt, &p.CreatedAt, &createdBy}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		p.ResolvedAt = &resolvedAt.Time
+	}
+	if createdBy.Valid {
+		p.CreatedBy = &createdBy.UUID
+	}
+	return &p, nil
+}
+
+// OpenPromises returns the open promises with the current amount due on
+// their invoice, which is 0 once the invoice is paid
+func (r *CollectionsRepository) OpenPromises(ctx context.Context, orgID uuid.UUID) ([]types.PromiseCheck, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+promiseColumns+`,
+			CASE WHEN i.payment_state IN ('not_paid', 'partial') AND i.state = 'posted' AND i.deleted_at IS NULL
+				THEN i.amount_residual ELSE 0 END
+		FROM collection_promises p
+		JOIN invoices i ON i.id = p.invoice_id
+		WHERE p.organization_id = $1 AND p.status = 'open'
+		ORDER BY p.promised_date
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open promises: %w", err)
+	}
+	defer rows.Close()
+
+	var checks []types.PromiseCheck
+	for rows.Next() {
+		var amountDue float64
+		p, err := scanPromise(rows, &amountDue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promise: %w", err)
+		}
+		checks = append(checks, types.PromiseCheck{Promise: *p, AmountDue: amountDue})
+	}
+	return checks, rows.Err()
+}
+
+func (r *CollectionsRepository) CreatePromise(ctx context.Context, promise types.Promise) (*types.Promise, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO collection_promises (
+			organization_id, invoice_id, promised_amount, promised_date, amount_due_at_promise, note, created_by
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id
+	`, promise.OrganizationID, promise.InvoiceID, promise.PromisedAmount, promise.PromisedDate,
+		promise.AmountDueAtPromise, promise.Note, promise.CreatedBy).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrPromiseOpen
+		}
+		return nil, fmt.Errorf("failed to create promise: %w", err)
+	}
+	return r.FindPromise(ctx, promise.OrganizationID, id)
+}
+
+func (r *CollectionsRepository) FindPromise(ctx context.Context, orgID, id uuid.UUID) (*types.Promise, error) {
+	p, err := scanPromise(r.db.QueryRowContext(ctx, `
+		SELECT `+promiseColumns+` FROM collection_promises p WHERE p.organization_id = $1 AND p.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("promise %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find promise: %w", err)
+	}
+	return p, nil
+}
+
+func (r *CollectionsRepository) ListPromises(ctx context.Context, filter types.PromiseFilter) ([]types.Promise, error) {
+	where := []string{"p.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.InvoiceID != nil {
+		args = append(args, *filter.InvoiceID)
+		where = append(where, fmt.Sprintf("p.invoice_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("p.status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + promiseColumns + ` FROM collection_promises p WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY p.promised_date DESC, p.created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promises: %w", err)
+	}
+	defer rows.Close()
+
+	promises := []types.Promise{}
+	for rows.Next() {
+		p, err := scanPromise(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promise: %w", err)
+		}
+		promises = append(promises, *p)
+	}
+	return promises, rows.Err()
+}
+
+// ResolvePromise closes an open promise
+func (r *CollectionsRepository) ResolvePromise(ctx context.Context, orgID, id uuid.UUID, status types.PromiseStatus, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE collection_promises SET status = $3, resolved_at = $4
+		WHERE organization_id = $1 AND id = $2 AND status = 'open'
+	`, orgID, id, status, at)
+	if err != nil {
+		return fmt.Errorf("failed to resolve promise: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("open promise %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) ListAssignments(ctx context.Context, orgID uuid.UUID, agentID *uuid.UUID) ([]types.Assignment, error) {
+	query := `
+		SELECT a.partner_id, COALESCE(c.display_name, c.name), a.agent_id, a.assigned_at, a.assigned_by
+		FROM collection_assignments a
+		JOIN contacts c ON c.id = a.partner_id
+		WHERE a.organization_id = $1`
+	args := []interface{}{orgID}
+	if agentID != nil {
+		args = append(args, *agentID)
+		query += ` AND a.agent_id = $2`
+	}
+	query += ` ORDER BY a.agent_id, 2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []types.Assignment{}
+	for rows.Next() {
+		var a types.Assignment
+		var assignedBy uuid.NullUUID
+		if err := rows.Scan(&a.PartnerID, &a.PartnerName, &a.AgentID, &a.AssignedAt, &assignedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		if assignedBy.Valid {
+			a.AssignedBy = &assignedBy.UUID
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// Assign moves customers to an agent's work queue. All customers must be
+// contacts of the organization.
+func (r *CollectionsRepository) Assign(ctx context.Context, orgID, userID uuid.UUID, request types.AssignmentRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, partnerID := range request.PartnerIDs {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO collection_assignments (organization_id, partner_id, agent_id, assigned_by)
+			SELECT $1, id, $3, $4 FROM contacts WHERE id = $2 AND organization_id = $1 AND deleted_at IS NULL
+			ON CONFLICT (organization_id, partner_id) DO UPDATE SET
+				agent_id = EXCLUDED.agent_id, assigned_by = EXCLUDED.assigned_by, assigned_at = now()
+		`, orgID, partnerID, request.AgentID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to assign customer: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("customer %s %w", partnerID, ErrNotFound)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *CollectionsRepository) Unassign(ctx context.Context, orgID, partnerID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM collection_assignments WHERE organization_id = $1 AND partner_id = $2
+	`, orgID, partnerID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign customer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("assignment %w", ErrNotFound)
+	}
+	return nil
+}
+
+// Aging buckets the amount due on open invoices by days past due as of a date
+func (r *CollectionsRepository) Aging(ctx context.Context, orgID uuid.UUID, asOf time.Time) (*types.AgingSnapshot, error) {
+	s := types.AgingSnapshot{Date: asOf}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE days <= 0), 0),
+			COALESCE(SUM(amount) FILTER (WHERE days BETWEEN 1 AND 30), 0),
+			COALESCE(SUM(amount) FILTER (WHERE days BETWEEN 31 AND 60), 0),
+			COALESCE(SUM(amount) FILTER (WHERE days BETWEEN 61 AND 90), 0),
+			COALESCE(SUM(amount) FILTER (WHERE days > 90), 0),
+			COUNT(*) FILTER (WHERE days > 0),
+			COALESCE(SUM(amount * days) FILTER (WHERE days > 0) / NULLIF(SUM(amount) FILTER (WHERE days > 0), 0), 0)
+		FROM (
+			SELECT i.amount_residual AS amount, $2::date - `+dueDate+` AS days
+			FROM invoices i WHERE i.organization_id = $1 AND `+openInvoice+`
+		) x
+	`, orgID, asOf).Scan(&s.NotDue, &s.Days1To30, &s.Days31To60, &s.Days61To90, &s.DaysOver90,
+		&s.OverdueInvoices, &s.AverageDaysOverdue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute aging: %w", err)
+	}
+	s.Overdue = s.Days1To30 + s.Days31To60 + s.Days61To90 + s.DaysOver90
+	return &s, nil
+}
+
+// SaveSnapshot records the aging of a day, replacing an earlier run's
+func (r *CollectionsRepository) SaveSnapshot(ctx context.Context, orgID uuid.UUID, snapshot types.AgingSnapshot) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO collection_aging_snapshots (
+			organization_id, snapshot_date, not_due, days_1_30, days_31_60, days_61_90, days_over_90,
+			overdue_invoices, average_days_overdue
+		) VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (organization_id, snapshot_date) DO UPDATE SET
+			not_due = EXCLUDED.not_due, days_1_30 = EXCLUDED.days_1_30, days_31_60 = EXCLUDED.days_31_60,
+			days_61_90 = EXCLUDED.days_61_90, days_over_90 = EXCLUDED.days_over_90,
+			overdue_invoices = EXCLUDED.overdue_invoices, average_days_overdue = EXCLUDED.average_days_overdue,
+			created_at = now()
+	`, orgID, snapshot.Date, snapshot.NotDue, snapshot.Days1To30, snapshot.Days31To60, snapshot.Days61To90,
+		snapshot.DaysOver90, snapshot.OverdueInvoices, snapshot.AverageDaysOverdue)
+	if err != nil {
+		return fmt.Errorf("failed to save aging snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots between from and to, inclusive, oldest first
+func (r *CollectionsRepository) ListSnapshots(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.AgingSnapshot, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT snapshot_date, not_due, days_1_30, days_31_60, days_61_90, days_over_90,
+			overdue_invoices, average_days_overdue
+		FROM collection_aging_snapshots
+		WHERE organization_id = $1 AND snapshot_date BETWEEN $2::date AND $3::date
+		ORDER BY snapshot_date
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aging snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []types.AgingSnapshot{}
+	for rows.Next() {
+		var s types.AgingSnapshot
+		if err := rows.Scan(&s.Date, &s.NotDue, &s.Days1To30, &s.Days31To60, &s.Days61To90, &s.DaysOver90,
+			&s.OverdueInvoices, &s.AverageDaysOverdue); err != nil {
+			return nil, fmt.Errorf("failed to scan aging snapshot: %w", err)
+		}
+		s.Overdue = s.Days1To30 + s.Days31To60 + s.Days61To90 + s.DaysOver90
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,661 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/types"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RunInterval is how often scheduled dunning runs
+	RunInterval = time.Hour
+	// DefaultPageSize is the page size of the queue, notice and promise lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of the queue, notice and promise lists
+	MaxPageSize = 500
+	// DefaultAgingDays is the period of the aging report when none is given
+	DefaultAgingDays = 90
+	// sendTimeout bounds the delivery of one dunning email
+	sendTimeout = 30 * time.Second
+)
+
+var (
+	// ErrInvalid wraps validation failures of levels, promises and assignments
+	ErrInvalid = errors.New("invalid request")
+	// ErrPromiseClosed is returned when cancelling a promise that is no longer open
+	ErrPromiseClosed = errors.New("promise to pay is not open")
+)
+
+// AuthService defines the permission check used by the collections service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer delivers dunning emails
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// ConsentChecker tells whether a contact allows being reached on a channel
+type ConsentChecker interface {
+	Allows(ctx context.Context, orgID, contactID uuid.UUID, channel string) (bool, error)
+}
+
+// CollectionsService sends escalating dunning notices on overdue customer
+// invoices, tracks promises to pay, fills the work queues of collection
+// agents and reports how receivables aging evolves
+type CollectionsService struct {
+	repo        repository.CollectionsRepo
+	authService AuthService
+	mailer      Mailer
+	consents    ConsentChecker
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewCollectionsService(repo repository.CollectionsRepo, authService AuthService, logger *slog.Logger) *CollectionsService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CollectionsService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails dunning notices to customers. Without a mailer notices
+// are recorded as skipped and agents follow up by other means.
+func (s *CollectionsService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetConsentChecker skips the notices of customers who opted out of email
+func (s *CollectionsService) SetConsentChecker(consents ConsentChecker) {
+	s.consents = consents
+}
+
+// today is the current date at midnight UTC
+func (s *CollectionsService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+// ListLevels returns the organization's dunning levels
+func (s *CollectionsService) ListLevels(ctx context.Context, orgID uuid.UUID) ([]types.DunningLevel, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListLevels(ctx, orgID)
+}
+
+// GetLevel returns a dunning level
+func (s *CollectionsService) GetLevel(ctx context.Context, orgID, id uuid.UUID) (*types.DunningLevel, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindLevel(ctx, orgID, id)
+}
+
+// CreateLevel validates and creates a dunning level
+func (s *CollectionsService) CreateLevel(ctx context.Context, orgID, userID uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.validateLevel(ctx, orgID, nil, &request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateLevel(ctx, orgID, userID, request)
+}
+
+// UpdateLevel validates and replaces a dunning level. Notices already sent
+// keep the fee and text they were sent with.
+func (s *CollectionsService) UpdateLevel(ctx context.Context, orgID, userID, id uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.validateLevel(ctx, orgID, &id, &request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateLevel(ctx, orgID, userID, id, request)
+}
+
+// DeleteLevel removes a dunning level
+func (s *CollectionsService) DeleteLevel(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteLevel(ctx, orgID, id)
+}
+
+// validateLevel checks a level on its own, then that active levels still
+// escalate: each level needs more days overdue than the one below it
+func (s *CollectionsService) validateLevel(ctx context.Context, orgID uuid.UUID, id *uuid.UUID, request *types.DunningLevelRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.EmailSubject = strings.TrimSpace(request.EmailSubject)
+	if request.Level < 1 {
+		return fmt.Errorf("%w: level must be at least 1", ErrInvalid)
+	}
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if request.DaysOverdue < 1 {
+		return fmt.Errorf("%w: days_overdue must be at least 1", ErrInvalid)
+	}
+	if request.FeeFixed < 0 {
+		return fmt.Errorf("%w: fee_fixed cannot be negative", ErrInvalid)
+	}
+	if request.FeePercent < 0 || request.FeePercent > 100 {
+		return fmt.Errorf("%w: fee_percent must be between 0 and 100", ErrInvalid)
+	}
+	if request.EmailSubject == "" || strings.TrimSpace(request.EmailBody) == "" {
+		return fmt.Errorf("%w: email subject and body are required", ErrInvalid)
+	}
+	if _, err := render(request.EmailSubject, types.NoticeData{}); err != nil {
+		return fmt.Errorf("%w: email subject: %v", ErrInvalid, err)
+	}
+	if _, err := render(request.EmailBody, types.NoticeData{}); err != nil {
+		return fmt.Errorf("%w: email body: %v", ErrInvalid, err)
+	}
+	if request.Active != nil && !*request.Active {
+		return nil
+	}
+
+	existing, err := s.repo.ListLevels(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, l := range existing {
+		if !l.Active || (id != nil && l.ID == *id) || l.Level == request.Level {
+			continue
+		}
+		if l.Level < request.Level && l.DaysOverdue >= request.DaysOverdue {
+			return fmt.Errorf("%w: level %d needs more than the %d days overdue of level %d",
+				ErrInvalid, request.Level, l.DaysOverdue, l.Level)
+		}
+		if l.Level > request.Level && l.DaysOverdue <= request.DaysOverdue {
+			return fmt.Errorf("%w: level %d needs fewer than the %d days overdue of level %d",
+				ErrInvalid, request.Level, l.DaysOverdue, l.Level)
+		}
+	}
+	return nil
+}
+
+// render executes a dunning email template
+func render(text string, data types.NoticeData) (string, error) {
+	tmpl, err := template.New("dunning").Funcs(template.FuncMap(templates.DefaultFuncMap())).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Run sends the dunning notices that are due in the organization and
+// settles promises to pay whose date passed
+func (s *CollectionsService) Run(ctx context.Context, orgID, userID uuid.UUID) (*types.RunResult, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.run(ctx, orgID, &userID)
+}
+
+// RunAll runs dunning and records the day's aging for every organization
+// with open customer invoices. It is called by the scheduler, outside of
+// any user session.
+func (s *CollectionsService) RunAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		result, err := s.run(ctx, orgID, nil)
+		if err == nil {
+			err = s.snapshot(ctx, orgID)
+		}
+		if err != nil {
+			failed++
+			s.logger.Error("Dunning run failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if len(result.Notices) > 0 || result.PromisesKept > 0 || result.PromisesBroken > 0 {
+			s.logger.Info("Dunning run completed", "organization_id", orgID, "notices", len(result.Notices),
+				"promises_kept", result.PromisesKept, "promises_broken", result.PromisesBroken)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("dunning failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *CollectionsService) run(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID) (*types.RunResult, error) {
+	result := &types.RunResult{Notices: []types.Notice{}}
+	if err := s.settlePromises(ctx, orgID, result); err != nil {
+		return nil, err
+	}
+
+	levels, err := s.repo.ListLevels(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	var active []types.DunningLevel
+	for _, l := range levels {
+		if l.Active {
+			active = append(active, l)
+		}
+	}
+	if len(active) == 0 {
+		return result, nil
+	}
+
+	today := s.today()
+	invoices, err := s.repo.OverdueInvoices(ctx, types.QueueFilter{OrganizationID: orgID, AsOf: today})
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range invoices {
+		if inv.Promise != nil {
+			continue
+		}
+		level := nextLevel(active, inv.DaysOverdue, inv.LastLevel)
+		if level == nil {
+			continue
+		}
+		notice, err := s.sendNotice(ctx, orgID, userID, inv, *level)
+		if err != nil {
+			return nil, err
+		}
+		if notice != nil {
+			result.Notices = append(result.Notices, *notice)
+		}
+	}
+	return result, nil
+}
+
+// settlePromises keeps promises whose invoice dropped by the promised
+// amount and breaks the others once their date has passed
+func (s *CollectionsService) settlePromises(ctx context.Context, orgID uuid.UUID, result *types.RunResult) error {
+	checks, err := s.repo.OpenPromises(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	now, today := s.now(), s.today()
+	for _, check := range checks {
+		status := promiseOutcome(check, today)
+		if status == types.PromiseOpen {
+			continue
+		}
+		if err := s.repo.ResolvePromise(ctx, orgID, check.Promise.ID, status, now); err != nil {
+			return err
+		}
+		if status == types.PromiseKept {
+			result.PromisesKept++
+		} else {
+			result.PromisesBroken++
+		}
+	}
+	return nil
+}
+
+// promiseOutcome is kept once the promised amount was paid, broken when it
+// was not by the promised date, and open until then
+func promiseOutcome(check types.PromiseCheck, today time.Time) types.PromiseStatus {
+	paid := check.Promise.AmountDueAtPromise - check.AmountDue
+	if paid >= check.Promise.PromisedAmount-0.005 {
+		return types.PromiseKept
+	}
+	if today.After(check.Promise.PromisedDate) {
+		return types.PromiseBroken
+	}
+	return types.PromiseOpen
+}
+
+// nextLevel returns the highest active level an invoice has reached and not
+// been sent yet. Levels in between are skipped, so an invoice that is
+// already long overdue when dunning starts gets the matching notice.
+func nextLevel(levels []types.DunningLevel, daysOverdue, lastLevel int) *types.DunningLevel {
+	var next *types.DunningLevel
+	for i := range levels {
+		l := &levels[i]
+		if l.Level <= lastLevel || l.DaysOverdue > daysOverdue {
+			continue
+		}
+		if next == nil || l.Level > next.Level {
+			next = l
+		}
+	}
+	return next
+}
+
+// noticeFee is the fixed fee plus the percentage of the amount due
+func noticeFee(level types.DunningLevel, amountDue float64) float64 {
+	return roundAmount(level.FeeFixed + amountDue*level.FeePercent/100)
+}
+
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// sendNotice renders, emails and records the notice of a level. It
+// returns nil when the level was reached concurrently.
+func (s *CollectionsService) sendNotice(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, inv types.OpenInvoice, level types.DunningLevel) (*types.Notice, error) {
+	fee := noticeFee(level, inv.AmountDue)
+	data := types.NoticeData{
+		InvoiceName:  inv.InvoiceName,
+		CustomerName: inv.PartnerName,
+		DueDate:      inv.DueDate.Format("2006-01-02"),
+		DaysOverdue:  inv.DaysOverdue,
+		AmountDue:    inv.AmountDue,
+		Fee:          fee,
+		TotalFees:    roundAmount(inv.Fees + fee),
+		TotalDue:     roundAmount(inv.AmountDue + inv.Fees + fee),
+		Level:        level.Level,
+	}
+
+	notice := types.Notice{
+		OrganizationID: orgID,
+		InvoiceID:      inv.InvoiceID,
+		InvoiceName:    inv.InvoiceName,
+		LevelID:        &level.ID,
+		Level:          level.Level,
+		DaysOverdue:    inv.DaysOverdue,
+		AmountDue:      inv.AmountDue,
+		Fee:            fee,
+		Recipient:      inv.PartnerEmail,
+		Status:         types.NoticeSent,
+		SentAt:         s.now(),
+		CreatedBy:      userID,
+	}
+
+	var err error
+	if notice.Subject, err = render(level.EmailSubject, data); err == nil {
+		notice.Body, err = render(level.EmailBody, data)
+	}
+	switch {
+	case err != nil:
+		// Templates are checked when saved, so this is a bad template from
+		// before validation; record it so the level gets fixed
+		notice.Status, notice.Error = types.NoticeFailed, fmt.Sprintf("failed to render email: %v", err)
+	case inv.PartnerEmail == "":
+		notice.Status, notice.Error = types.NoticeSkipped, "customer has no email address"
+	case s.mailer == nil:
+		notice.Status, notice.Error = types.NoticeSkipped, "no mail server configured"
+	case !s.allowsEmail(ctx, orgID, inv):
+		notice.Status, notice.Error = types.NoticeSkipped, "customer opted out of email"
+	default:
+		sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, orgID), sendTimeout)
+		err := s.mailer.Send(sendCtx, inv.PartnerEmail, notice.Subject, notice.Body)
+		cancel()
+		if err != nil {
+			s.logger.Warn("Failed to send dunning notice", "invoice_id", inv.InvoiceID, "level", level.Level, "error", err)
+			notice.Status, notice.Error = types.NoticeFailed, err.Error()
+		}
+	}
+
+	return s.repo.SaveNotice(ctx, notice)
+}
+
+// allowsEmail reports whether the customer of the invoice may be emailed.
+// Customers are only held back once they opted out, so a failed check lets
+// the notice go.
+func (s *CollectionsService) allowsEmail(ctx context.Context, orgID uuid.UUID, inv types.OpenInvoice) bool {
+	if s.consents == nil || inv.PartnerID == uuid.Nil {
+		return true
+	}
+	allowed, err := s.consents.Allows(ctx, orgID, inv.PartnerID, "email")
+	if err != nil {
+		s.logger.Warn("Failed to check customer email consent", "invoice_id", inv.InvoiceID, "partner_id", inv.PartnerID, "error", err)
+		return true
+	}
+	return allowed
+}
+
+// snapshot records today's aging
+func (s *CollectionsService) snapshot(ctx context.Context, orgID uuid.UUID) error {
+	aging, err := s.repo.Aging(ctx, orgID, s.today())
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveSnapshot(ctx, orgID, *aging)
+}
+
+// ListNotices returns dunning notices, newest first
+func (s *CollectionsService) ListNotices(ctx context.Context, filter types.NoticeFilter) ([]types.Notice, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	switch filter.Status {
+	case "", types.NoticeSent, types.NoticeFailed, types.NoticeSkipped:
+	default:
+		return nil, fmt.Errorf("%w: unknown notice status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit = pageSize(filter.Limit)
+	return s.repo.ListNotices(ctx, filter)
+}
+
+// Queue returns the overdue invoices of an agent's customers, or of
+// customers without an agent, longest overdue first
+func (s *CollectionsService) Queue(ctx context.Context, filter types.QueueFilter) ([]types.OpenInvoice, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Unassigned && filter.AgentID != nil {
+		return nil, fmt.Errorf("%w: agent_id and unassigned cannot be combined", ErrInvalid)
+	}
+	filter.AsOf = s.today()
+	filter.Limit = pageSize(filter.Limit)
+	return s.repo.OverdueInvoices(ctx, filter)
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// ListPromises returns promises to pay
+func (s *CollectionsService) ListPromises(ctx context.Context, filter types.PromiseFilter) ([]types.Promise, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown promise status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit = pageSize(filter.Limit)
+	return s.repo.ListPromises(ctx, filter)
+}
+
+// CreatePromise records a customer's promise to pay an open invoice, which
+// pauses dunning on the invoice until the promise is settled
+func (s *CollectionsService) CreatePromise(ctx context.Context, orgID, userID uuid.UUID, request types.PromiseRequest) (*types.Promise, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:work"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.PromisedAmount <= 0 {
+		return nil, fmt.Errorf("%w: promised_amount must be positive", ErrInvalid)
+	}
+	if request.PromisedDate.IsZero() {
+		return nil, fmt.Errorf("%w: promised_date is required", ErrInvalid)
+	}
+	today := s.today()
+	promisedDate := request.PromisedDate.UTC().Truncate(24 * time.Hour)
+	if promisedDate.Before(today) {
+		return nil, fmt.Errorf("%w: promised_date is in the past", ErrInvalid)
+	}
+
+	inv, err := s.repo.FindInvoice(ctx, orgID, request.InvoiceID, today)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Promise != nil {
+		return nil, repository.ErrPromiseOpen
+	}
+	amount := roundAmount(request.PromisedAmount)
+	if amount > inv.AmountDue {
+		return nil, fmt.Errorf("%w: promised_amount is more than the %.2f due", ErrInvalid, inv.AmountDue)
+	}
+
+	return s.repo.CreatePromise(ctx, types.Promise{
+		OrganizationID:     orgID,
+		InvoiceID:          inv.InvoiceID,
+		PromisedAmount:     amount,
+		PromisedDate:       promisedDate,
+		AmountDueAtPromise: inv.AmountDue,
+		Status:             types.PromiseOpen,
+		Note:               strings.TrimSpace(request.Note),
+		CreatedBy:          &userID,
+	})
+}
+
+// CancelPromise withdraws an open promise, resuming dunning on the invoice
+func (s *CollectionsService) CancelPromise(ctx context.Context, orgID, id uuid.UUID) (*types.Promise, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:work"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	promise, err := s.repo.FindPromise(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if promise.Status != types.PromiseOpen {
+		return nil, fmt.Errorf("%w: promise is %s", ErrPromiseClosed, promise.Status)
+	}
+	if err := s.repo.ResolvePromise(ctx, orgID, id, types.PromiseCancelled, s.now()); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPromise(ctx, orgID, id)
+}
+
+// ListAssignments returns which agent works which customer
+func (s *CollectionsService) ListAssignments(ctx context.Context, orgID uuid.UUID, agentID *uuid.UUID) ([]types.Assignment, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListAssignments(ctx, orgID, agentID)
+}
+
+// Assign puts customers in an agent's work queue
+func (s *CollectionsService) Assign(ctx context.Context, orgID, userID uuid.UUID, request types.AssignmentRequest) ([]types.Assignment, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.AgentID == uuid.Nil {
+		return nil, fmt.Errorf("%w: agent_id is required", ErrInvalid)
+	}
+	if len(request.PartnerIDs) == 0 {
+		return nil, fmt.Errorf("%w: partner_ids is required", ErrInvalid)
+	}
+	seen := make(map[uuid.UUID]bool, len(request.PartnerIDs))
+	partnerIDs := request.PartnerIDs[:0]
+	for _, id := range request.PartnerIDs {
+		if !seen[id] {
+			seen[id] = true
+			partnerIDs = append(partnerIDs, id)
+		}
+	}
+	request.PartnerIDs = partnerIDs
+
+	if err := s.repo.Assign(ctx, orgID, userID, request); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAssignments(ctx, orgID, &request.AgentID)
+}
+
+// Unassign takes a customer out of its agent's work queue
+func (s *CollectionsService) Unassign(ctx context.Context, orgID, partnerID uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "collections:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.Unassign(ctx, orgID, partnerID)
+}
+
+// Aging returns the current aging with the daily snapshots between from
+// and to, and how aging moved over the period. The period defaults to the
+// last DefaultAgingDays days.
+func (s *CollectionsService) Aging(ctx context.Context, orgID uuid.UUID, from, to *time.Time) (*types.AgingReport, error) {
+	if err := s.authService.CheckPermission(ctx, "collections:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	today := s.today()
+	end := today
+	if to != nil {
+		end = to.UTC().Truncate(24 * time.Hour)
+	}
+	start := end.AddDate(0, 0, -DefaultAgingDays)
+	if from != nil {
+		start = from.UTC().Truncate(24 * time.Hour)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalid)
+	}
+
+	current, err := s.repo.Aging(ctx, orgID, today)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.repo.ListSnapshots(ctx, orgID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AgingReport{
+		Current:   *current,
+		Snapshots: snapshots,
+		Trend:     agingTrend(snapshots),
+	}, nil
+}
+
+// agingTrend compares the first and last snapshot. Aging improved when less
+// is overdue, or as much but for fewer days.
+func agingTrend(snapshots []types.AgingSnapshot) *types.AgingTrend {
+	if len(snapshots) < 2 {
+		return nil
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Date.Before(snapshots[j].Date) })
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+
+	trend := &types.AgingTrend{
+		From:              first.Date,
+		To:                last.Date,
+		OverdueChange:     roundAmount(last.Overdue - first.Overdue),
+		Over90Change:      roundAmount(last.DaysOver90 - first.DaysOver90),
+		Over90ShareChange: roundAmount(over90Share(last) - over90Share(first)),
+		AverageDaysChange: roundAmount(last.AverageDaysOverdue - first.AverageDaysOverdue),
+	}
+	if first.Overdue > 0 {
+		percent := roundAmount(trend.OverdueChange / first.Overdue * 100)
+		trend.OverdueChangePercent = &percent
+	}
+	trend.Improved = trend.OverdueChange < 0 || (trend.OverdueChange == 0 && trend.AverageDaysChange < 0)
+	return trend
+}
+
+// over90Share is the percentage of the overdue amount more than 90 days past due
+func over90Share(s types.AgingSnapshot) float64 {
+	if s.Overdue <= 0 {
+		return 0
+	}
+	return s.DaysOver90 / s.Overdue * 100
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/collections/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCollectionsRepo struct {
+	levels   []types.DunningLevel
+	invoices []types.OpenInvoice
+	notices  []types.Notice
+	promises []types.PromiseCheck
+	resolved map[uuid.UUID]types.PromiseStatus
+	created  []types.Promise
+}
+
+func newFakeCollectionsRepo() *fakeCollectionsRepo {
+	return &fakeCollectionsRepo{resolved: make(map[uuid.UUID]types.PromiseStatus)}
+}
+
+func (f *fakeCollectionsRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeCollectionsRepo) ListLevels(ctx context.Context, orgID uuid.UUID) ([]types.DunningLevel, error) {
+	return f.levels, nil
+}
+
+func (f *fakeCollectionsRepo) FindLevel(ctx context.Context, orgID, id uuid.UUID) (*types.DunningLevel, error) {
+	for i := range f.levels {
+		if f.levels[i].ID == id {
+			return &f.levels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("dunning level %w", repository.ErrNotFound)
+}
+
+func (f *fakeCollectionsRepo) CreateLevel(ctx context.Context, orgID, userID uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	level := types.DunningLevel{ID: uuid.New(), Level: request.Level, Name: request.Name, DaysOverdue: request.DaysOverdue, Active: true}
+	f.levels = append(f.levels, level)
+	return &level, nil
+}
+
+func (f *fakeCollectionsRepo) UpdateLevel(ctx context.Context, orgID, userID, id uuid.UUID, request types.DunningLevelRequest) (*types.DunningLevel, error) {
+	return f.FindLevel(ctx, orgID, id)
+}
+
+func (f *fakeCollectionsRepo) DeleteLevel(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeCollectionsRepo) FindInvoice(ctx context.Context, orgID, invoiceID uuid.UUID, asOf time.Time) (*types.OpenInvoice, error) {
+	for i := range f.invoices {
+		if f.invoices[i].InvoiceID == invoiceID {
+			return &f.invoices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("open invoice %w", repository.ErrNotFound)
+}
+
+func (f *fakeCollectionsRepo) OverdueInvoices(ctx context.Context, filter types.QueueFilter) ([]types.OpenInvoice, error) {
+	return f.invoices, nil
+}
+
+func (f *fakeCollectionsRepo) SaveNotice(ctx context.Context, notice types.Notice) (*types.Notice, error) {
+	notice.ID = uuid.New()
+	f.notices = append(f.notices, notice)
+	return &notice, nil
+}
+
+func (f *fakeCollectionsRepo) ListNotices(ctx context.Context, filter types.NoticeFilter) ([]types.Notice, error) {
+	return f.notices, nil
+}
+
+func (f *fakeCollectionsRepo) OpenPromises(ctx context.Context, orgID uuid.UUID) ([]types.PromiseCheck, error) {
+	return f.promises, nil
+}
+
+func (f *fakeCollectionsRepo) CreatePromise(ctx context.Context, promise types.Promise) (*types.Promise, error) {
+	promise.ID = uuid.New()
+	f.created = append(f.created, promise)
+	return &promise, nil
+}
+
+func (f *fakeCollectionsRepo) FindPromise(ctx context.Context, orgID, id uuid.UUID) (*types.Promise, error) {
+	for _, p := range f.created {
+		if p.ID == id {
+			if status, ok := f.resolved[id]; ok {
+				p.Status = status
+			}
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("promise %w", repository.ErrNotFound)
+}
+
+func (f *fakeCollectionsRepo) ListPromises(ctx context.Context, filter types.PromiseFilter) ([]types.Promise, error) {
+	return f.created, nil
+}
+
+func (f *fakeCollectionsRepo) ResolvePromise(ctx context.Context, orgID, id uuid.UUID, status types.PromiseStatus, at time.Time) error {
+	f.resolved[id] = status
+	return nil
+}
+
+func (f *fakeCollectionsRepo) ListAssignments(ctx context.Context, orgID uuid.UUID, agentID *uuid.UUID) ([]types.Assignment, error) {
+	return nil, nil
+}
+
+func (f *fakeCollectionsRepo) Assign(ctx context.Context, orgID, userID uuid.UUID, request types.AssignmentRequest) error {
+	return nil
+}
+
+func (f *fakeCollectionsRepo) Unassign(ctx context.Context, orgID, partnerID uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeCollectionsRepo) Aging(ctx context.Context, orgID uuid.UUID, asOf time.Time) (*types.AgingSnapshot, error) {
+	return &types.AgingSnapshot{Date: asOf}, nil
+}
+
+func (f *fakeCollectionsRepo) SaveSnapshot(ctx context.Context, orgID uuid.UUID, snapshot types.AgingSnapshot) error {
+	return nil
+}
+
+func (f *fakeCollectionsRepo) ListSnapshots(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.AgingSnapshot, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+// fakeConsents allows emailing every contact but optedOut
+type fakeConsents struct {
+	optedOut uuid.UUID
+}
+
+func (c fakeConsents) Allows(_ context.Context, _, contactID uuid.UUID, channel string) (bool, error) {
+	return channel != "email" || contactID != c.optedOut, nil
+}
+
+type fakeMailer struct {
+	sent []string
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, to+": "+subject)
+	return nil
+}
+
+func newTestService(repo *fakeCollectionsRepo) *CollectionsService {
+	svc := NewCollectionsService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testLevels() []types.DunningLevel {
+	return []types.DunningLevel{
+		{ID: uuid.New(), Level: 1, Name: "Reminder", DaysOverdue: 7, Active: true,
+			EmailSubject: "Reminder: {{.InvoiceName}}", EmailBody: "{{.AmountDue}} was due on {{.DueDate}}"},
+		{ID: uuid.New(), Level: 2, Name: "Second notice", DaysOverdue: 30, FeeFixed: 10, Active: true,
+			EmailSubject: "Second notice: {{.InvoiceName}}", EmailBody: "Please pay {{printf \"%.2f\" .TotalDue}}"},
+		{ID: uuid.New(), Level: 3, Name: "Final notice", DaysOverdue: 60, FeeFixed: 25, FeePercent: 2, Active: true,
+			EmailSubject: "Final notice: {{.InvoiceName}}", EmailBody: "Pay {{printf \"%.2f\" .TotalDue}} within 7 days"},
+	}
+}
+
+func TestNextLevel(t *testing.T) {
+	levels := testLevels()
+
+	assert.Nil(t, nextLevel(levels, 3, 0))
+	assert.Equal(t, 1, nextLevel(levels, 10, 0).Level)
+	assert.Nil(t, nextLevel(levels, 10, 1))
+	assert.Equal(t, 2, nextLevel(levels, 45, 1).Level)
+	// Dunning starting late jumps straight to the matching level
+	assert.Equal(t, 3, nextLevel(levels, 75, 0).Level)
+	assert.Nil(t, nextLevel(levels, 200, 3))
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+
+	t.Run("sends the reached level with its fee", func(t *testing.T) {
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		repo.invoices = []types.OpenInvoice{
+			{InvoiceID: uuid.New(), InvoiceName: "INV/001", PartnerName: "Acme", PartnerEmail: "ap@acme.test",
+				DueDate: day(1, 15), DaysOverdue: 75, AmountDue: 1000, LastLevel: 2, Fees: 10},
+			{InvoiceID: uuid.New(), InvoiceName: "INV/002", PartnerEmail: "ap@globex.test",
+				DueDate: day(3, 28), DaysOverdue: 3, AmountDue: 500},
+			{InvoiceID: uuid.New(), InvoiceName: "INV/003", PartnerEmail: "ap@initech.test",
+				DueDate: day(2, 1), DaysOverdue: 58, AmountDue: 800,
+				Promise: &types.Promise{PromisedAmount: 800, PromisedDate: day(4, 5)}},
+		}
+		mailer := &fakeMailer{}
+		svc := newTestService(repo)
+		svc.SetMailer(mailer)
+
+		result, err := svc.Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		require.Len(t, result.Notices, 1)
+		notice := result.Notices[0]
+		assert.Equal(t, 3, notice.Level)
+		assert.Equal(t, types.NoticeSent, notice.Status)
+		// 25 fixed plus 2% of 1,000
+		assert.Equal(t, 45.0, notice.Fee)
+		assert.Equal(t, "Final notice: INV/001", notice.Subject)
+		assert.Equal(t, "Pay 1055.00 within 7 days", notice.Body)
+		assert.Equal(t, &userID, notice.CreatedBy)
+		assert.Equal(t, []string{"ap@acme.test: Final notice: INV/001"}, mailer.sent)
+	})
+
+	t.Run("records skipped and failed notices", func(t *testing.T) {
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		repo.invoices = []types.OpenInvoice{
+			{InvoiceID: uuid.New(), InvoiceName: "INV/004", DaysOverdue: 8, AmountDue: 100},
+		}
+
+		result, err := newTestService(repo).Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		require.Len(t, result.Notices, 1)
+		assert.Equal(t, types.NoticeSkipped, result.Notices[0].Status)
+		assert.Equal(t, "customer has no email address", result.Notices[0].Error)
+
+		repo.invoices[0].PartnerEmail = "ap@umbrella.test"
+		svc := newTestService(repo)
+		result, err = svc.Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, types.NoticeSkipped, result.Notices[0].Status)
+		assert.Equal(t, "no mail server configured", result.Notices[0].Error)
+
+		svc.SetMailer(&fakeMailer{err: errors.New("connection refused")})
+		result, err = svc.Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, types.NoticeFailed, result.Notices[0].Status)
+		assert.Equal(t, "connection refused", result.Notices[0].Error)
+	})
+
+	t.Run("skips customers who opted out of email", func(t *testing.T) {
+		optedOut := uuid.New()
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		repo.invoices = []types.OpenInvoice{
+			{InvoiceID: uuid.New(), InvoiceName: "INV/005", PartnerID: optedOut, PartnerEmail: "ap@hooli.test", DaysOverdue: 8, AmountDue: 100},
+			{InvoiceID: uuid.New(), InvoiceName: "INV/006", PartnerID: uuid.New(), PartnerEmail: "ap@wayne.test", DaysOverdue: 8, AmountDue: 100},
+		}
+		mailer := &fakeMailer{}
+		svc := newTestService(repo)
+		svc.SetMailer(mailer)
+		svc.SetConsentChecker(fakeConsents{optedOut: optedOut})
+
+		result, err := svc.Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		require.Len(t, result.Notices, 2)
+		assert.Equal(t, types.NoticeSkipped, result.Notices[0].Status)
+		assert.Equal(t, "customer opted out of email", result.Notices[0].Error)
+		assert.Equal(t, types.NoticeSent, result.Notices[1].Status)
+		assert.Equal(t, []string{"ap@wayne.test: Reminder: INV/006"}, mailer.sent)
+	})
+
+	t.Run("inactive levels are not sent", func(t *testing.T) {
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		repo.levels[0].Active = false
+		repo.invoices = []types.OpenInvoice{{InvoiceID: uuid.New(), DaysOverdue: 10, AmountDue: 100}}
+
+		result, err := newTestService(repo).Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		assert.Empty(t, result.Notices)
+	})
+
+	t.Run("settles promises", func(t *testing.T) {
+		kept, broken, pending := uuid.New(), uuid.New(), uuid.New()
+		repo := newFakeCollectionsRepo()
+		repo.promises = []types.PromiseCheck{
+			{Promise: types.Promise{ID: kept, PromisedAmount: 300, PromisedDate: day(4, 10), AmountDueAtPromise: 1000}, AmountDue: 700},
+			{Promise: types.Promise{ID: broken, PromisedAmount: 300, PromisedDate: day(3, 30), AmountDueAtPromise: 1000}, AmountDue: 800},
+			{Promise: types.Promise{ID: pending, PromisedAmount: 300, PromisedDate: day(3, 31), AmountDueAtPromise: 1000}, AmountDue: 1000},
+		}
+
+		result, err := newTestService(repo).Run(ctx, orgID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.PromisesKept)
+		assert.Equal(t, 1, result.PromisesBroken)
+		assert.Equal(t, map[uuid.UUID]types.PromiseStatus{kept: types.PromiseKept, broken: types.PromiseBroken}, repo.resolved)
+	})
+}
+
+func TestCreateLevel(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	valid := func() types.DunningLevelRequest {
+		return types.DunningLevelRequest{
+			Level: 4, Name: "Collections agency", DaysOverdue: 90,
+			EmailSubject: "{{.InvoiceName}} sent to collections", EmailBody: "Dear {{.CustomerName}}",
+		}
+	}
+
+	invalid := map[string]func(*types.DunningLevelRequest){
+		"level zero":          func(r *types.DunningLevelRequest) { r.Level = 0 },
+		"missing name":        func(r *types.DunningLevelRequest) { r.Name = " " },
+		"no days overdue":     func(r *types.DunningLevelRequest) { r.DaysOverdue = 0 },
+		"negative fee":        func(r *types.DunningLevelRequest) { r.FeeFixed = -1 },
+		"percent over 100":    func(r *types.DunningLevelRequest) { r.FeePercent = 101 },
+		"missing body":        func(r *types.DunningLevelRequest) { r.EmailBody = "" },
+		"unparsable template": func(r *types.DunningLevelRequest) { r.EmailBody = "{{.AmountDue" },
+		"unknown field":       func(r *types.DunningLevelRequest) { r.EmailSubject = "{{.Customer}}" },
+		"does not escalate":   func(r *types.DunningLevelRequest) { r.DaysOverdue = 60 },
+		"escalates too late":  func(r *types.DunningLevelRequest) { r.Level, r.DaysOverdue = 2, 60 },
+	}
+	for name, change := range invalid {
+		t.Run(name, func(t *testing.T) {
+			repo := newFakeCollectionsRepo()
+			repo.levels = testLevels()
+			req := valid()
+			change(&req)
+			_, err := newTestService(repo).CreateLevel(ctx, orgID, userID, req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		level, err := newTestService(repo).CreateLevel(ctx, orgID, userID, valid())
+		require.NoError(t, err)
+		assert.Equal(t, 4, level.Level)
+	})
+
+	t.Run("inactive levels skip the escalation check", func(t *testing.T) {
+		repo := newFakeCollectionsRepo()
+		repo.levels = testLevels()
+		req := valid()
+		req.DaysOverdue = 30
+		inactive := false
+		req.Active = &inactive
+		_, err := newTestService(repo).CreateLevel(ctx, orgID, userID, req)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUpdateLevelIgnoresItself(t *testing.T) {
+	repo := newFakeCollectionsRepo()
+	repo.levels = testLevels()
+	level := repo.levels[1]
+
+	_, err := newTestService(repo).UpdateLevel(context.Background(), uuid.New(), uuid.New(), level.ID, types.DunningLevelRequest{
+		Level: 2, Name: level.Name, DaysOverdue: 45, EmailSubject: level.EmailSubject, EmailBody: level.EmailBody,
+	})
+	assert.NoError(t, err)
+}
+
+func TestCreatePromise(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	invoiceID, promisedID := uuid.New(), uuid.New()
+
+	repo := newFakeCollectionsRepo()
+	repo.invoices = []types.OpenInvoice{
+		{InvoiceID: invoiceID, AmountDue: 1000},
+		{InvoiceID: promisedID, AmountDue: 500, Promise: &types.Promise{Status: types.PromiseOpen}},
+	}
+	svc := newTestService(repo)
+
+	_, err := svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{InvoiceID: invoiceID, PromisedDate: day(4, 15)})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{InvoiceID: invoiceID, PromisedAmount: 100, PromisedDate: day(3, 30)})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{InvoiceID: invoiceID, PromisedAmount: 1200, PromisedDate: day(4, 15)})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{InvoiceID: promisedID, PromisedAmount: 100, PromisedDate: day(4, 15)})
+	assert.ErrorIs(t, err, repository.ErrPromiseOpen)
+
+	_, err = svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{InvoiceID: uuid.New(), PromisedAmount: 100, PromisedDate: day(4, 15)})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	promise, err := svc.CreatePromise(ctx, orgID, userID, types.PromiseRequest{
+		InvoiceID: invoiceID, PromisedAmount: 400, PromisedDate: time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC), Note: " By wire ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, day(3, 31), promise.PromisedDate)
+	assert.Equal(t, 1000.0, promise.AmountDueAtPromise)
+	assert.Equal(t, "By wire", promise.Note)
+
+	cancelled, err := svc.CancelPromise(ctx, orgID, promise.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.PromiseCancelled, cancelled.Status)
+
+	_, err = svc.CancelPromise(ctx, orgID, promise.ID)
+	assert.ErrorIs(t, err, ErrPromiseClosed)
+}
+
+func TestAgingTrend(t *testing.T) {
+	assert.Nil(t, agingTrend([]types.AgingSnapshot{{Date: day(3, 1)}}))
+
+	trend := agingTrend([]types.AgingSnapshot{
+		{Date: day(3, 31), Overdue: 8000, DaysOver90: 800, AverageDaysOverdue: 35},
+		{Date: day(1, 1), Overdue: 10000, DaysOver90: 2500, AverageDaysOverdue: 48},
+	})
+	require.NotNil(t, trend)
+	assert.Equal(t, day(1, 1), trend.From)
+	assert.Equal(t, day(3, 31), trend.To)
+	assert.Equal(t, -2000.0, trend.OverdueChange)
+	require.NotNil(t, trend.OverdueChangePercent)
+	assert.Equal(t, -20.0, *trend.OverdueChangePercent)
+	assert.Equal(t, -1700.0, trend.Over90Change)
+	assert.Equal(t, -15.0, trend.Over90ShareChange)
+	assert.Equal(t, -13.0, trend.AverageDaysChange)
+	assert.True(t, trend.Improved)
+
+	trend = agingTrend([]types.AgingSnapshot{
+		{Date: day(1, 1)},
+		{Date: day(2, 1), Overdue: 500, DaysOver90: 0, AverageDaysOverdue: 10},
+	})
+	assert.Nil(t, trend.OverdueChangePercent)
+	assert.False(t, trend.Improved)
+}
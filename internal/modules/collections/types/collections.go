@@ -0,0 +1,242 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoticeStatus is the delivery outcome of a dunning notice
+type NoticeStatus string
+
+const (
+	NoticeSent NoticeStatus = "sent"
+	// NoticeFailed notices are retried by the next dunning run
+	NoticeFailed NoticeStatus = "failed"
+	// NoticeSkipped notices were not emailed, because the customer has no
+	// email address or no mail server is configured
+	NoticeSkipped NoticeStatus = "skipped"
+)
+
+// PromiseStatus is the state of a promise to pay
+type PromiseStatus string
+
+const (
+	PromiseOpen      PromiseStatus = "open"
+	PromiseKept      PromiseStatus = "kept"
+	PromiseBroken    PromiseStatus = "broken"
+	PromiseCancelled PromiseStatus = "cancelled"
+)
+
+// IsValid reports whether the status is known
+func (s PromiseStatus) IsValid() bool {
+	switch s {
+	case PromiseOpen, PromiseKept, PromiseBroken, PromiseCancelled:
+		return true
+	}
+	return false
+}
+
+// DunningLevel is a step of the dunning escalation. An overdue invoice
+// reaches the level DaysOverdue days after its due date.
+type DunningLevel struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Level          int       `json:"level"`
+	Name           string    `json:"name"`
+	DaysOverdue    int       `json:"days_overdue"`
+	FeeFixed       float64   `json:"fee_fixed"`
+	FeePercent     float64   `json:"fee_percent"`
+	EmailSubject   string    `json:"email_subject"`
+	EmailBody      string    `json:"email_body"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DunningLevelRequest creates or replaces a dunning level. EmailSubject and
+// EmailBody are text templates over NoticeData.
+type DunningLevelRequest struct {
+	Level        int     `json:"level"`
+	Name         string  `json:"name"`
+	DaysOverdue  int     `json:"days_overdue"`
+	FeeFixed     float64 `json:"fee_fixed"`
+	FeePercent   float64 `json:"fee_percent"`
+	EmailSubject string  `json:"email_subject"`
+	EmailBody    string  `json:"email_body"`
+	Active       *bool   `json:"active,omitempty"`
+}
+
+// NoticeData is what dunning email templates are rendered with
+type NoticeData struct {
+	InvoiceName  string
+	CustomerName string
+	DueDate      string
+	DaysOverdue  int
+	AmountDue    float64
+	Fee          float64
+	// TotalFees includes the fee of this notice
+	TotalFees float64
+	// TotalDue is the amount due plus all dunning fees
+	TotalDue float64
+	Level    int
+}
+
+// OpenInvoice is a posted customer invoice with an amount still due
+type OpenInvoice struct {
+	InvoiceID    uuid.UUID `json:"invoice_id"`
+	InvoiceName  string    `json:"invoice_name"`
+	PartnerID    uuid.UUID `json:"partner_id"`
+	PartnerName  string    `json:"partner_name"`
+	PartnerEmail string    `json:"partner_email,omitempty"`
+	DueDate      time.Time `json:"due_date"`
+	DaysOverdue  int       `json:"days_overdue"`
+	AmountTotal  float64   `json:"amount_total"`
+	AmountDue    float64   `json:"amount_due"`
+	// LastLevel is the highest dunning level reached, 0 before the first notice
+	LastLevel    int        `json:"last_level"`
+	LastNoticeAt *time.Time `json:"last_notice_at,omitempty"`
+	Fees         float64    `json:"fees"`
+	AgentID      *uuid.UUID `json:"agent_id,omitempty"`
+	// Promise is the open promise to pay, which pauses dunning
+	Promise *Promise `json:"promise,omitempty"`
+}
+
+// QueueFilter selects overdue invoices for a collection work queue
+type QueueFilter struct {
+	OrganizationID uuid.UUID
+	AgentID        *uuid.UUID
+	// Unassigned selects invoices of customers without an agent
+	Unassigned bool
+	AsOf       time.Time
+	Limit      int
+	Offset     int
+}
+
+// Notice is a dunning notice sent on an overdue invoice
+type Notice struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	InvoiceID      uuid.UUID    `json:"invoice_id"`
+	InvoiceName    string       `json:"invoice_name"`
+	LevelID        *uuid.UUID   `json:"level_id,omitempty"`
+	Level          int          `json:"level"`
+	DaysOverdue    int          `json:"days_overdue"`
+	AmountDue      float64      `json:"amount_due"`
+	Fee            float64      `json:"fee"`
+	Recipient      string       `json:"recipient,omitempty"`
+	Subject        string       `json:"subject"`
+	Body           string       `json:"body"`
+	Status         NoticeStatus `json:"status"`
+	Error          string       `json:"error,omitempty"`
+	SentAt         time.Time    `json:"sent_at"`
+	CreatedBy      *uuid.UUID   `json:"created_by,omitempty"`
+}
+
+// NoticeFilter narrows the notice list
+type NoticeFilter struct {
+	OrganizationID uuid.UUID
+	InvoiceID      *uuid.UUID
+	Status         NoticeStatus
+	Limit          int
+	Offset         int
+}
+
+// Promise is a customer's promise to pay part or all of an invoice by a date
+type Promise struct {
+	ID                 uuid.UUID     `json:"id"`
+	OrganizationID     uuid.UUID     `json:"organization_id"`
+	InvoiceID          uuid.UUID     `json:"invoice_id"`
+	PromisedAmount     float64       `json:"promised_amount"`
+	PromisedDate       time.Time     `json:"promised_date"`
+	AmountDueAtPromise float64       `json:"amount_due_at_promise"`
+	Status             PromiseStatus `json:"status"`
+	Note               string        `json:"note,omitempty"`
+	ResolvedAt         *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt          time.Time     `json:"created_at"`
+	CreatedBy          *uuid.UUID    `json:"created_by,omitempty"`
+}
+
+// PromiseRequest records a promise to pay
+type PromiseRequest struct {
+	InvoiceID      uuid.UUID `json:"invoice_id"`
+	PromisedAmount float64   `json:"promised_amount"`
+	PromisedDate   time.Time `json:"promised_date"`
+	Note           string    `json:"note,omitempty"`
+}
+
+// PromiseFilter narrows the promise list
+type PromiseFilter struct {
+	OrganizationID uuid.UUID
+	InvoiceID      *uuid.UUID
+	Status         PromiseStatus
+	Limit          int
+	Offset         int
+}
+
+// PromiseCheck is an open promise with the invoice's current amount due
+type PromiseCheck struct {
+	Promise   Promise
+	AmountDue float64
+}
+
+// Assignment puts a customer in a collection agent's work queue
+type Assignment struct {
+	PartnerID   uuid.UUID  `json:"partner_id"`
+	PartnerName string     `json:"partner_name"`
+	AgentID     uuid.UUID  `json:"agent_id"`
+	AssignedAt  time.Time  `json:"assigned_at"`
+	AssignedBy  *uuid.UUID `json:"assigned_by,omitempty"`
+}
+
+// AssignmentRequest assigns customers to an agent, replacing their
+// previous agent
+type AssignmentRequest struct {
+	AgentID    uuid.UUID   `json:"agent_id"`
+	PartnerIDs []uuid.UUID `json:"partner_ids"`
+}
+
+// AgingSnapshot is the amount due on open customer invoices by days past
+// due on a date
+type AgingSnapshot struct {
+	Date       time.Time `json:"date"`
+	NotDue     float64   `json:"not_due"`
+	Days1To30  float64   `json:"days_1_30"`
+	Days31To60 float64   `json:"days_31_60"`
+	Days61To90 float64   `json:"days_61_90"`
+	DaysOver90 float64   `json:"days_over_90"`
+	// Overdue is the sum of the overdue buckets
+	Overdue         float64 `json:"overdue"`
+	OverdueInvoices int     `json:"overdue_invoices"`
+	// AverageDaysOverdue is weighted by amount due
+	AverageDaysOverdue float64 `json:"average_days_overdue"`
+}
+
+// AgingTrend compares the first and last snapshot of a period. Negative
+// changes are improvements.
+type AgingTrend struct {
+	From                 time.Time `json:"from"`
+	To                   time.Time `json:"to"`
+	OverdueChange        float64   `json:"overdue_change"`
+	OverdueChangePercent *float64  `json:"overdue_change_percent,omitempty"`
+	Over90Change         float64   `json:"over_90_change"`
+	// Over90ShareChange is the change, in percentage points, of the share of
+	// overdue amounts more than 90 days past due
+	Over90ShareChange float64 `json:"over_90_share_change"`
+	AverageDaysChange float64 `json:"average_days_change"`
+	Improved          bool    `json:"improved"`
+}
+
+// AgingReport is the current aging with its history over a period
+type AgingReport struct {
+	Current   AgingSnapshot   `json:"current"`
+	Snapshots []AgingSnapshot `json:"snapshots"`
+	Trend     *AgingTrend     `json:"trend,omitempty"`
+}
+
+// RunResult summarizes a dunning run
+type RunResult struct {
+	PromisesKept   int      `json:"promises_kept"`
+	PromisesBroken int      `json:"promises_broken"`
+	Notices        []Notice `json:"notices"`
+}
@@ -0,0 +1,519 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CommissionHandler handles commission plans, deal splits, entries,
+// disputes and payouts
+type CommissionHandler struct {
+	service *service.CommissionService
+}
+
+func NewCommissionHandler(service *service.CommissionService) *CommissionHandler {
+	return &CommissionHandler{service: service}
+}
+
+func (h *CommissionHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/commissions/plans", h.ListPlans)
+	router.POST("/api/commissions/plans", h.CreatePlan)
+	router.GET("/api/commissions/plans/:id", h.GetPlan)
+	router.PUT("/api/commissions/plans/:id", h.UpdatePlan)
+	router.PUT("/api/commissions/plans/:id/members", h.SetPlanMembers)
+
+	router.GET("/api/commissions/splits", h.GetSplits)
+	router.PUT("/api/commissions/splits", h.SaveSplits)
+
+	router.POST("/api/commissions/accrue", h.Accrue)
+	router.GET("/api/commissions/entries", h.ListEntries)
+	router.GET("/api/commissions/entries/:id", h.GetEntry)
+	router.POST("/api/commissions/entries/:id/dispute", h.Dispute)
+	router.POST("/api/commissions/entries/:id/resolve", h.Resolve)
+	router.POST("/api/commissions/adjustments", h.Adjust)
+
+	router.GET("/api/commissions/payout-report", h.PayoutReport)
+	router.GET("/api/commissions/payouts", h.ListPayouts)
+	router.POST("/api/commissions/payouts", h.CreatePayout)
+	router.GET("/api/commissions/payouts/:id", h.GetPayout)
+}
+
+// ListPlans handles GET /api/commissions/plans
+func (h *CommissionHandler) ListPlans(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	plans, err := h.service.ListPlans(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plans)
+}
+
+// CreatePlan handles POST /api/commissions/plans
+func (h *CommissionHandler) CreatePlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.CreatePlan(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, plan)
+}
+
+// GetPlan handles GET /api/commissions/plans/:id
+func (h *CommissionHandler) GetPlan(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid plan ID", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.GetPlan(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// UpdatePlan handles PUT /api/commissions/plans/:id
+func (h *CommissionHandler) UpdatePlan(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid plan ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.UpdatePlan(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// SetPlanMembers handles PUT /api/commissions/plans/:id/members
+func (h *CommissionHandler) SetPlanMembers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid plan ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PlanMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.SetPlanMembers(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// GetSplits handles GET /api/commissions/splits?source_type=lead&source_id=...
+func (h *CommissionHandler) GetSplits(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	sourceID, err := uuid.Parse(q.Get("source_id"))
+	if err != nil {
+		http.Error(w, "Invalid source_id", http.StatusBadRequest)
+		return
+	}
+
+	splits, err := h.service.GetSplits(r.Context(), authCtx.OrganizationID, types.SourceType(q.Get("source_type")), sourceID)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, splits)
+}
+
+// SaveSplits handles PUT /api/commissions/splits, replacing how a deal's
+// credit is split
+func (h *CommissionHandler) SaveSplits(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.DealSplits
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	splits, err := h.service.SaveSplits(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, splits)
+}
+
+// Accrue handles POST /api/commissions/accrue, accruing commissions without
+// waiting for the scheduled run
+func (h *CommissionHandler) Accrue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Accrue(r.Context(), authCtx.OrganizationID, authCtx.UserID)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ListEntries handles GET /api/commissions/entries with optional user_id,
+// payout_id, unpaid, in_dispute, from, to, limit and offset filters
+func (h *CommissionHandler) ListEntries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.EntryFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Unpaid:         q.Get("unpaid") == "true",
+		InDispute:      q.Get("in_dispute") == "true",
+	}
+	for name, dest := range map[string]**uuid.UUID{"user_id": &filter.UserID, "payout_id": &filter.PayoutID} {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = &id
+		}
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, _ = strconv.Atoi(v)
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, _ = strconv.Atoi(v)
+	}
+
+	entries, err := h.service.ListEntries(r.Context(), filter)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// GetEntry handles GET /api/commissions/entries/:id
+func (h *CommissionHandler) GetEntry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.GetEntry(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// Dispute handles POST /api/commissions/entries/:id/dispute
+func (h *CommissionHandler) Dispute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.Dispute(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// Resolve handles POST /api/commissions/entries/:id/resolve
+func (h *CommissionHandler) Resolve(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.Resolve(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// Adjust handles POST /api/commissions/adjustments
+func (h *CommissionHandler) Adjust(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.AdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.Adjust(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// PayoutReport handles GET /api/commissions/payout-report with an optional
+// period_end, defaulting to now. format=csv returns the payroll export.
+func (h *CommissionHandler) PayoutReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var periodEnd time.Time
+	if v := r.URL.Query().Get("period_end"); v != "" {
+		var err error
+		if periodEnd, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid period_end date", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.PayoutReport(r.Context(), authCtx.OrganizationID, periodEnd)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writePayoutCSV(w, "commission-payout-"+report.PeriodEnd.Format("2006-01-02")+".csv", report.Lines)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ListPayouts handles GET /api/commissions/payouts
+func (h *CommissionHandler) ListPayouts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	payouts, err := h.service.ListPayouts(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payouts)
+}
+
+// CreatePayout handles POST /api/commissions/payouts
+func (h *CommissionHandler) CreatePayout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payout, err := h.service.CreatePayout(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, payout)
+}
+
+// GetPayout handles GET /api/commissions/payouts/:id. format=csv returns the
+// payroll export.
+func (h *CommissionHandler) GetPayout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid payout ID", http.StatusBadRequest)
+		return
+	}
+
+	payout, err := h.service.GetPayout(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCommissionError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writePayoutCSV(w, "commission-payout-"+payout.PeriodEnd.Format("2006-01-02")+".csv", payout.Lines)
+		return
+	}
+	writeJSON(w, http.StatusOK, payout)
+}
+
+// writePayoutCSV writes one row per rep for payroll import
+func writePayoutCSV(w http.ResponseWriter, filename string, lines []types.PayoutLine) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"user_id", "email", "entries", "accrued", "adjustments", "total"})
+	for _, l := range lines {
+		cw.Write([]string{
+			l.UserID.String(),
+			l.Email,
+			strconv.Itoa(l.Entries),
+			strconv.FormatFloat(l.Accrued, 'f', 2, 64),
+			strconv.FormatFloat(l.Adjustments, 'f', 2, 64),
+			strconv.FormatFloat(l.Total, 'f', 2, 64),
+		})
+	}
+	cw.Flush()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCommissionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicateName), errors.Is(err, repository.ErrAlreadyAccrued),
+		errors.Is(err, service.ErrInDispute), errors.Is(err, service.ErrNotInDispute):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrNothingToPay):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
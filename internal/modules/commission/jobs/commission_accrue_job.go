@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeCommissionAccrue = "commission.accrue"
+
+// CommissionAccrueJobHandler handles queued commission accrual jobs
+type CommissionAccrueJobHandler struct {
+	commissionService *service.CommissionService
+}
+
+func NewCommissionAccrueJobHandler(commissionService *service.CommissionService) *CommissionAccrueJobHandler {
+	return &CommissionAccrueJobHandler{
+		commissionService: commissionService,
+	}
+}
+
+// Handle processes a commission accrual job
+func (h *CommissionAccrueJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.commissionService.AccrueAll(ctx); err != nil {
+		return fmt.Errorf("failed to accrue commissions: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *CommissionAccrueJobHandler) JobType() string {
+	return JobTypeCommissionAccrue
+}
+
+// Scheduler runs commission accrual on a fixed interval, so won leads and
+// paid invoices accrue without anyone asking
+type Scheduler struct {
+	handler     *CommissionAccrueJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *CommissionAccrueJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs accrual every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeCommissionAccrue, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeCommissionAccrue, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled commission accrual failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule commission accrual", "error", err)
+	}
+}
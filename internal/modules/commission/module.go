@@ -0,0 +1,76 @@
+package commission
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CommissionModule represents the sales commission module
+type CommissionModule struct {
+	commissionHandler *handler.CommissionHandler
+	scheduler         *jobs.Scheduler
+	logger            *slog.Logger
+}
+
+// NewCommissionModule creates a new commission module
+func NewCommissionModule() *CommissionModule {
+	return &CommissionModule{}
+}
+
+// Name returns the module name
+func (m *CommissionModule) Name() string {
+	return "commission"
+}
+
+// Init initializes the commission module and starts scheduled accrual
+func (m *CommissionModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "commission")
+	m.logger.Info("Initializing commission module")
+
+	// Create repositories
+	commissionRepo := repository.NewCommissionRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	commissionService := service.NewCommissionService(commissionRepo, authAdapter, m.logger)
+
+	// Create scheduled accrual
+	accrueJobHandler := jobs.NewCommissionAccrueJobHandler(commissionService)
+	m.scheduler = jobs.NewScheduler(accrueJobHandler, service.RunInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.commissionHandler = handler.NewCommissionHandler(commissionService)
+
+	m.logger.Info("Commission module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers commission module routes
+func (m *CommissionModule) RegisterRoutes(router interface{}) {
+	if m.commissionHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.commissionHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the commission module
+func (m *CommissionModule) RegisterEventHandlers(bus interface{}) {
+	// Accrual is schedule driven, reading won leads and paid invoices
+	// directly so no deal is missed when an event is lost
+}
+
+// Health checks the health of the commission module
+func (m *CommissionModule) Health() error {
+	return nil
+}
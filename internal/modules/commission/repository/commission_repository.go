@@ -0,0 +1,799 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a plan, entry, payout or deal does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateName is returned when another plan already has the name
+	ErrDuplicateName = errors.New("commission plan name already used")
+	// ErrAlreadyAccrued is returned when changing the splits of a deal that
+	// already accrued commissions
+	ErrAlreadyAccrued = errors.New("deal already accrued commissions")
+	// ErrNothingToPay is returned when a payout would not pay any entry
+	ErrNothingToPay = errors.New("no commissions to pay")
+)
+
+// CommissionRepo defines the interface for commission repository operations
+type CommissionRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListPlans(ctx context.Context, orgID uuid.UUID) ([]types.Plan, error)
+	FindPlan(ctx context.Context, orgID, id uuid.UUID) (*types.Plan, error)
+	CreatePlan(ctx context.Context, orgID, userID uuid.UUID, request types.PlanRequest) (*types.Plan, error)
+	UpdatePlan(ctx context.Context, orgID, userID, id uuid.UUID, request types.PlanRequest) (*types.Plan, error)
+	SetPlanMembers(ctx context.Context, orgID, planID uuid.UUID, userIDs []uuid.UUID) error
+	FindSplits(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, sourceID uuid.UUID) ([]types.Split, error)
+	SaveSplits(ctx context.Context, orgID, userID uuid.UUID, splits types.DealSplits) error
+	PendingSources(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, since time.Time) ([]types.Source, error)
+	PeriodBase(ctx context.Context, orgID, userID, planID uuid.UUID, from, to time.Time) (float64, error)
+	CreateEntry(ctx context.Context, entry types.Entry) (*types.Entry, error)
+	ListEntries(ctx context.Context, filter types.EntryFilter) ([]types.Entry, error)
+	FindEntry(ctx context.Context, orgID, id uuid.UUID) (*types.Entry, error)
+	DisputeEntry(ctx context.Context, orgID, id, userID uuid.UUID, reason string, at time.Time) error
+	ResolveDispute(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time, adjustment *types.Entry) error
+	PayoutReport(ctx context.Context, orgID uuid.UUID, periodEnd time.Time) ([]types.PayoutLine, int, error)
+	CreatePayout(ctx context.Context, orgID, userID uuid.UUID, periodEnd time.Time) (*types.Payout, error)
+	ListPayouts(ctx context.Context, orgID uuid.UUID) ([]types.Payout, error)
+	FindPayout(ctx context.Context, orgID, id uuid.UUID) (*types.Payout, error)
+}
+
+// CommissionRepository stores commission plans, splits, entries and
+// payouts, and reads the won leads and paid invoices commissions accrue on
+type CommissionRepository struct {
+	db *sql.DB
+}
+
+// Ensure CommissionRepository implements CommissionRepo interface
+var _ CommissionRepo = &CommissionRepository{}
+
+func NewCommissionRepository(db *sql.DB) *CommissionRepository {
+	return &CommissionRepository{db: db}
+}
+
+// ListOrganizationIDs returns the organizations with an active plan
+func (r *CommissionRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT organization_id FROM commission_plans WHERE active`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const planColumns = `
+	p.id, p.organization_id, p.name, COALESCE(p.description, ''), p.basis, p.plan_type, p.rate, p.tiers,
+	p.effective_from, p.active, p.created_at, p.updated_at,
+	COALESCE((
+		SELECT json_agg(json_build_object('product_id', pr.product_id, 'rate', pr.rate) ORDER BY pr.product_id)
+		FROM commission_plan_rates pr WHERE pr.plan_id = p.id
+	), '[]'),
+	ARRAY(SELECT m.user_id::text FROM commission_plan_members m WHERE m.plan_id = p.id ORDER BY m.user_id)
+`
+
+func scanPlan(row interface{ Scan(...interface{}) error }) (*types.Plan, error) {
+	var p types.Plan
+	var tiers, rates []byte
+	var members pq.StringArray
+	err := row.Scan(&p.ID, &p.OrganizationID, &p.Name, &p.Description, &p.Basis, &p.Type, &p.Rate, &tiers,
+		&p.EffectiveFrom, &p.Active, &p.CreatedAt, &p.UpdatedAt, &rates, &members)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tiers, &p.Tiers); err != nil {
+		return nil, fmt.Errorf("invalid tiers: %w", err)
+	}
+	if err := json.Unmarshal(rates, &p.ProductRates); err != nil {
+		return nil, fmt.Errorf("invalid product rates: %w", err)
+	}
+	p.MemberIDs = make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan member: %w", err)
+		}
+		p.MemberIDs = append(p.MemberIDs, id)
+	}
+	return &p, nil
+}
+
+func (r *CommissionRepository) ListPlans(ctx context.Context, orgID uuid.UUID) ([]types.Plan, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+planColumns+` FROM commission_plans p WHERE p.organization_id = $1 ORDER BY p.name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commission plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []types.Plan{}
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan commission plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+	return plans, rows.Err()
+}
+
+func (r *CommissionRepository) FindPlan(ctx context.Context, orgID, id uuid.UUID) (*types.Plan, error) {
+	p, err := scanPlan(r.db.QueryRowContext(ctx, `
+		SELECT `+planColumns+` FROM commission_plans p WHERE p.organization_id = $1 AND p.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("commission plan %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find commission plan: %w", err)
+	}
+	return p, nil
+}
+
+func (r *CommissionRepository) CreatePlan(ctx context.Context, orgID, userID uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	return r.savePlan(ctx, orgID, userID, nil, request)
+}
+
+func (r *CommissionRepository) UpdatePlan(ctx context.Context, orgID, userID, id uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	return r.savePlan(ctx, orgID, userID, &id, request)
+}
+
+// savePlan inserts a plan, or replaces plan id, and its product rates
+func (r *CommissionRepository) savePlan(ctx context.Context, orgID, userID uuid.UUID, id *uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	tiers, err := json.Marshal(request.Tiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tiers: %w", err)
+	}
+	if request.Tiers == nil {
+		tiers = []byte("[]")
+	}
+	active := request.Active == nil || *request.Active
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var planID uuid.UUID
+	if id == nil {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO commission_plans (
+				organization_id, name, description, basis, plan_type, rate, tiers, effective_from, active,
+				created_by, updated_by
+			) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, COALESCE($8::date, CURRENT_DATE), $9, $10, $10)
+			RETURNING id
+		`, orgID, request.Name, request.Description, request.Basis, request.Type, request.Rate, tiers,
+			request.EffectiveFrom, active, userID).Scan(&planID)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			UPDATE commission_plans SET
+				name = $3, description = NULLIF($4, ''), basis = $5, plan_type = $6, rate = $7, tiers = $8,
+				effective_from = COALESCE($9::date, effective_from), active = $10, updated_by = $11, updated_at = now()
+			WHERE organization_id = $1 AND id = $2
+			RETURNING id
+		`, orgID, *id, request.Name, request.Description, request.Basis, request.Type, request.Rate, tiers,
+			request.EffectiveFrom, active, userID).Scan(&planID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("commission plan %w", ErrNotFound)
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateName, request.Name)
+		}
+		return nil, fmt.Errorf("failed to save commission plan: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM commission_plan_rates WHERE plan_id = $1`, planID); err != nil {
+		return nil, fmt.Errorf("failed to replace product rates: %w", err)
+	}
+	for _, rate := range request.ProductRates {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO commission_plan_rates (plan_id, product_id, rate)
+			SELECT $1, id, $3 FROM products WHERE id = $2 AND organization_id = $4
+		`, planID, rate.ProductID, rate.Rate, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save product rate: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit commission plan: %w", err)
+	}
+	return r.FindPlan(ctx, orgID, planID)
+}
+
+// SetPlanMembers replaces the reps on a plan
+func (r *CommissionRepository) SetPlanMembers(ctx context.Context, orgID, planID uuid.UUID, userIDs []uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM commission_plan_members WHERE organization_id = $1 AND plan_id = $2
+	`, orgID, planID); err != nil {
+		return fmt.Errorf("failed to remove plan members: %w", err)
+	}
+	for _, userID := range userIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO commission_plan_members (organization_id, plan_id, user_id) VALUES ($1, $2, $3)
+		`, orgID, planID, userID); err != nil {
+			return fmt.Errorf("failed to add plan member: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *CommissionRepository) FindSplits(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, sourceID uuid.UUID) ([]types.Split, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, share FROM commission_splits
+		WHERE organization_id = $1 AND source_type = $2 AND source_id = $3
+		ORDER BY share DESC, user_id
+	`, orgID, sourceType, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commission splits: %w", err)
+	}
+	defer rows.Close()
+
+	splits := []types.Split{}
+	for rows.Next() {
+		var s types.Split
+		if err := rows.Scan(&s.UserID, &s.Share); err != nil {
+			return nil, fmt.Errorf("failed to scan commission split: %w", err)
+		}
+		splits = append(splits, s)
+	}
+	return splits, rows.Err()
+}
+
+// SaveSplits replaces the splits of a lead or invoice that has not accrued
+// commissions yet
+func (r *CommissionRepository) SaveSplits(ctx context.Context, orgID, userID uuid.UUID, splits types.DealSplits) error {
+	table := "leads"
+	if splits.SourceType == types.SourceInvoice {
+		table = "invoices"
+	}
+
+	var exists, accrued bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM `+table+` WHERE id = $3 AND organization_id = $1 AND deleted_at IS NULL),
+			EXISTS (
+				SELECT 1 FROM commission_entries
+				WHERE organization_id = $1 AND entry_type = 'accrual' AND source_type = $2 AND source_id = $3
+			)
+	`, orgID, splits.SourceType, splits.SourceID).Scan(&exists, &accrued)
+	if err != nil {
+		return fmt.Errorf("failed to find deal: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %w", splits.SourceType, ErrNotFound)
+	}
+	if accrued {
+		return ErrAlreadyAccrued
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM commission_splits WHERE organization_id = $1 AND source_type = $2 AND source_id = $3
+	`, orgID, splits.SourceType, splits.SourceID); err != nil {
+		return fmt.Errorf("failed to remove commission splits: %w", err)
+	}
+	for _, s := range splits.Splits {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO commission_splits (organization_id, source_type, source_id, user_id, share, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, orgID, splits.SourceType, splits.SourceID, s.UserID, s.Share, userID); err != nil {
+			return fmt.Errorf("failed to save commission split: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// PendingSources returns the won leads or paid customer invoices earned on
+// or after since that have not accrued commissions, oldest first. Invoices
+// are earned on their last payment date.
+func (r *CommissionRepository) PendingSources(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, since time.Time) ([]types.Source, error) {
+	query := `
+		SELECT s.id, s.name, s.earned_on, s.amount, s.owner_id FROM (
+			SELECT l.id, l.name, COALESCE(l.date_closed, l.updated_at)::date AS earned_on,
+				COALESCE(l.expected_revenue, 0) AS amount, COALESCE(l.assigned_to, l.user_id) AS owner_id
+			FROM leads l
+			WHERE l.organization_id = $1 AND l.status = 'won' AND l.deleted_at IS NULL
+		) s
+	`
+	if sourceType == types.SourceInvoice {
+		query = `
+			SELECT s.id, s.name, s.earned_on, s.amount, s.owner_id FROM (
+				SELECT i.id, COALESCE(i.name, '') AS name,
+					COALESCE((
+						SELECT MAX(p.payment_date) FROM payment_invoice_allocation a
+						JOIN payments p ON p.id = a.payment_id
+						WHERE a.invoice_id = i.id AND p.state <> 'cancelled' AND p.deleted_at IS NULL
+					), i.updated_at::date) AS earned_on,
+					COALESCE(i.amount_untaxed, 0) AS amount, i.user_id AS owner_id
+				FROM invoices i
+				WHERE i.organization_id = $1 AND i.move_type = 'out_invoice' AND i.state = 'posted'
+					AND i.payment_state = 'paid' AND i.deleted_at IS NULL
+			) s
+		`
+	}
+	rows, err := r.db.QueryContext(ctx, query+`
+		WHERE s.earned_on >= $3::date
+			AND NOT EXISTS (
+				SELECT 1 FROM commission_entries e
+				WHERE e.organization_id = $1 AND e.entry_type = 'accrual' AND e.source_type = $2 AND e.source_id = s.id
+			)
+		ORDER BY s.earned_on, s.id
+	`, orgID, sourceType, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending %ss: %w", sourceType, err)
+	}
+	defer rows.Close()
+
+	var sources []types.Source
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		s := types.Source{Type: sourceType}
+		var ownerID uuid.NullUUID
+		if err := rows.Scan(&s.ID, &s.Name, &s.EarnedOn, &s.Amount, &ownerID); err != nil {
+			return nil, fmt.Errorf("failed to scan pending %s: %w", sourceType, err)
+		}
+		if ownerID.Valid {
+			s.OwnerID = &ownerID.UUID
+		}
+		index[s.ID] = len(sources)
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return sources, nil
+	}
+
+	ids := make([]string, 0, len(sources))
+	for _, s := range sources {
+		ids = append(ids, s.ID.String())
+	}
+
+	if sourceType == types.SourceInvoice {
+		lineRows, err := r.db.QueryContext(ctx, `
+			SELECT move_id, product_id, COALESCE(price_subtotal, 0) FROM invoice_lines
+			WHERE move_id = ANY($1::uuid[]) AND deleted_at IS NULL
+				AND COALESCE(display_type, 'product') = 'product' AND NOT COALESCE(exclude_from_invoice_tab, false)
+			ORDER BY move_id, sequence
+		`, pq.Array(ids))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list invoice lines: %w", err)
+		}
+		defer lineRows.Close()
+		for lineRows.Next() {
+			var invoiceID uuid.UUID
+			var productID uuid.NullUUID
+			var line types.SourceLine
+			if err := lineRows.Scan(&invoiceID, &productID, &line.Amount); err != nil {
+				return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+			}
+			if productID.Valid {
+				line.ProductID = &productID.UUID
+			}
+			i := index[invoiceID]
+			sources[i].Lines = append(sources[i].Lines, line)
+		}
+		if err := lineRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	splitRows, err := r.db.QueryContext(ctx, `
+		SELECT source_id, user_id, share FROM commission_splits
+		WHERE organization_id = $1 AND source_type = $2 AND source_id = ANY($3::uuid[])
+		ORDER BY source_id, share DESC, user_id
+	`, orgID, sourceType, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commission splits: %w", err)
+	}
+	defer splitRows.Close()
+	for splitRows.Next() {
+		var sourceID uuid.UUID
+		var split types.Split
+		if err := splitRows.Scan(&sourceID, &split.UserID, &split.Share); err != nil {
+			return nil, fmt.Errorf("failed to scan commission split: %w", err)
+		}
+		i := index[sourceID]
+		sources[i].Splits = append(sources[i].Splits, split)
+	}
+	return sources, splitRows.Err()
+}
+
+// PeriodBase sums the base the rep accrued on a plan for deals earned in
+// [from, to)
+func (r *CommissionRepository) PeriodBase(ctx context.Context, orgID, userID, planID uuid.UUID, from, to time.Time) (float64, error) {
+	var base float64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(base_amount), 0) FROM commission_entries
+		WHERE organization_id = $1 AND user_id = $2 AND plan_id = $3 AND entry_type = 'accrual'
+			AND earned_on >= $4::date AND earned_on < $5::date
+	`, orgID, userID, planID, from, to).Scan(&base)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum commission base: %w", err)
+	}
+	return base, nil
+}
+
+const entryColumns = `
+	id, organization_id, user_id, plan_id, entry_type, source_type, source_id, COALESCE(source_name, ''),
+	earned_on, base_amount, share, rate, amount, adjusts_entry_id, COALESCE(note, ''),
+	COALESCE(dispute_reason, ''), disputed_by, disputed_at, COALESCE(resolution_note, ''), resolved_by,
+	resolved_at, payout_id, created_at, created_by
+`
+
+func scanEntry(row interface{ Scan(...interface{}) error }) (*types.Entry, error) {
+	var e types.Entry
+	var planID, sourceID, adjustsEntryID, disputedBy, resolvedBy, payoutID, createdBy uuid.NullUUID
+	var sourceType sql.NullString
+	var disputedAt, resolvedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.OrganizationID, &e.UserID, &planID, &e.EntryType, &sourceType, &sourceID,
+		&e.SourceName, &e.EarnedOn, &e.BaseAmount, &e.Share, &e.Rate, &e.Amount, &adjustsEntryID, &e.Note,
+		&e.DisputeReason, &disputedBy, &disputedAt, &e.ResolutionNote, &resolvedBy, &resolvedAt, &payoutID,
+		&e.CreatedAt, &createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceType.Valid {
+		t := types.SourceType(sourceType.String)
+		e.SourceType = &t
+	}
+	for dest, v := range map[**uuid.UUID]uuid.NullUUID{
+		&e.PlanID: planID, &e.SourceID: sourceID, &e.AdjustsEntryID: adjustsEntryID, &e.DisputedBy: disputedBy,
+		&e.ResolvedBy: resolvedBy, &e.PayoutID: payoutID, &e.CreatedBy: createdBy,
+	} {
+		if v.Valid {
+			id := v.UUID
+			*dest = &id
+		}
+	}
+	if disputedAt.Valid {
+		e.DisputedAt = &disputedAt.Time
+	}
+	if resolvedAt.Valid {
+		e.ResolvedAt = &resolvedAt.Time
+	}
+	return &e, nil
+}
+
+// insertEntry inserts an entry. Accruals already recorded for the deal and
+// rep are skipped and return nil.
+func insertEntry(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, entry types.Entry) (*types.Entry, error) {
+	e, err := scanEntry(q.QueryRowContext(ctx, `
+		INSERT INTO commission_entries (
+			organization_id, user_id, plan_id, entry_type, source_type, source_id, source_name, earned_on,
+			base_amount, share, rate, amount, adjusts_entry_id, note, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8::date, $9, $10, $11, $12, $13, NULLIF($14, ''), $15)
+		ON CONFLICT (organization_id, source_type, source_id, user_id) WHERE entry_type = 'accrual' DO NOTHING
+		RETURNING `+entryColumns,
+		entry.OrganizationID, entry.UserID, entry.PlanID, entry.EntryType, entry.SourceType, entry.SourceID,
+		entry.SourceName, entry.EarnedOn, entry.BaseAmount, entry.Share, entry.Rate, entry.Amount,
+		entry.AdjustsEntryID, entry.Note, entry.CreatedBy))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to record commission entry: %w", err)
+	}
+	return e, nil
+}
+
+// CreateEntry records an accrual or adjustment. It returns nil when the
+// rep already accrued on the deal.
+func (r *CommissionRepository) CreateEntry(ctx context.Context, entry types.Entry) (*types.Entry, error) {
+	return insertEntry(ctx, r.db, entry)
+}
+
+func (r *CommissionRepository) ListEntries(ctx context.Context, filter types.EntryFilter) ([]types.Entry, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.UserID != nil {
+		add("user_id = $%d", *filter.UserID)
+	}
+	if filter.PayoutID != nil {
+		add("payout_id = $%d", *filter.PayoutID)
+	}
+	if filter.Unpaid {
+		where = append(where, "payout_id IS NULL")
+	}
+	if filter.InDispute {
+		where = append(where, "disputed_at IS NOT NULL AND resolved_at IS NULL")
+	}
+	if filter.From != nil {
+		add("earned_on >= $%d::date", *filter.From)
+	}
+	if filter.To != nil {
+		add("earned_on < $%d::date", *filter.To)
+	}
+
+	query := `SELECT ` + entryColumns + ` FROM commission_entries WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY earned_on DESC, created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commission entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan commission entry: %w", err)
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *CommissionRepository) FindEntry(ctx context.Context, orgID, id uuid.UUID) (*types.Entry, error) {
+	e, err := scanEntry(r.db.QueryRowContext(ctx, `
+		SELECT `+entryColumns+` FROM commission_entries WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("commission entry %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find commission entry: %w", err)
+	}
+	return e, nil
+}
+
+// DisputeEntry opens a dispute on an entry, replacing a resolved one
+func (r *CommissionRepository) DisputeEntry(ctx context.Context, orgID, id, userID uuid.UUID, reason string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE commission_entries SET
+			dispute_reason = $3, disputed_by = $4, disputed_at = $5,
+			resolution_note = NULL, resolved_by = NULL, resolved_at = NULL
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, id, reason, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to dispute commission entry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("commission entry %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ResolveDispute closes the dispute on an entry and records the adjustment,
+// if any, in the same transaction
+func (r *CommissionRepository) ResolveDispute(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time, adjustment *types.Entry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE commission_entries SET resolution_note = NULLIF($3, ''), resolved_by = $4, resolved_at = $5
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, id, note, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to resolve commission dispute: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("commission entry %w", ErrNotFound)
+	}
+	if adjustment != nil {
+		if _, err := insertEntry(ctx, tx, *adjustment); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// payableEntries selects the unpaid entries earned up to $2 that are not
+// held back by an open dispute
+const payableEntries = `
+	organization_id = $1 AND payout_id IS NULL AND earned_on <= $2::date
+	AND NOT (disputed_at IS NOT NULL AND resolved_at IS NULL)
+`
+
+const payoutLineColumns = `
+	e.user_id, COALESCE(u.email, ''), COUNT(*),
+	COALESCE(SUM(e.amount) FILTER (WHERE e.entry_type = 'accrual'), 0),
+	COALESCE(SUM(e.amount) FILTER (WHERE e.entry_type = 'adjustment'), 0),
+	SUM(e.amount)
+`
+
+func scanPayoutLines(rows *sql.Rows) ([]types.PayoutLine, error) {
+	defer rows.Close()
+	lines := []types.PayoutLine{}
+	for rows.Next() {
+		var l types.PayoutLine
+		if err := rows.Scan(&l.UserID, &l.Email, &l.Entries, &l.Accrued, &l.Adjustments, &l.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan payout line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// PayoutReport totals the payable entries per rep and counts the entries
+// held back by disputes
+func (r *CommissionRepository) PayoutReport(ctx context.Context, orgID uuid.UUID, periodEnd time.Time) ([]types.PayoutLine, int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+payoutLineColumns+`
+		FROM commission_entries e
+		LEFT JOIN auth.users u ON u.id = e.user_id
+		WHERE e.id IN (SELECT id FROM commission_entries WHERE `+payableEntries+`)
+		GROUP BY e.user_id, u.email
+		ORDER BY u.email, e.user_id
+	`, orgID, periodEnd)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to compute payout report: %w", err)
+	}
+	lines, err := scanPayoutLines(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var held int
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM commission_entries
+		WHERE organization_id = $1 AND payout_id IS NULL AND earned_on <= $2::date
+			AND disputed_at IS NOT NULL AND resolved_at IS NULL
+	`, orgID, periodEnd).Scan(&held)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count disputed entries: %w", err)
+	}
+	return lines, held, nil
+}
+
+// CreatePayout pays the payable entries earned up to periodEnd
+func (r *CommissionRepository) CreatePayout(ctx context.Context, orgID, userID uuid.UUID, periodEnd time.Time) (*types.Payout, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var payoutID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO commission_payouts (organization_id, period_end, created_by) VALUES ($1, $2::date, $3)
+		RETURNING id
+	`, orgID, periodEnd, userID).Scan(&payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payout: %w", err)
+	}
+
+	var count int
+	var total float64
+	err = tx.QueryRowContext(ctx, `
+		WITH paid AS (
+			UPDATE commission_entries SET payout_id = $3
+			WHERE `+payableEntries+`
+			RETURNING amount
+		)
+		SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM paid
+	`, orgID, periodEnd, payoutID).Scan(&count, &total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pay commission entries: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrNothingToPay
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE commission_payouts SET total = $2, entry_count = $3 WHERE id = $1
+	`, payoutID, total, count); err != nil {
+		return nil, fmt.Errorf("failed to total payout: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payout: %w", err)
+	}
+	return r.FindPayout(ctx, orgID, payoutID)
+}
+
+const payoutColumns = `id, organization_id, period_end, total, entry_count, created_at, created_by`
+
+func scanPayout(row interface{ Scan(...interface{}) error }) (*types.Payout, error) {
+	var p types.Payout
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&p.ID, &p.OrganizationID, &p.PeriodEnd, &p.Total, &p.EntryCount, &p.CreatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		p.CreatedBy = &createdBy.UUID
+	}
+	return &p, nil
+}
+
+func (r *CommissionRepository) ListPayouts(ctx context.Context, orgID uuid.UUID) ([]types.Payout, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+payoutColumns+` FROM commission_payouts WHERE organization_id = $1
+		ORDER BY period_end DESC, created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []types.Payout{}
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts = append(payouts, *p)
+	}
+	return payouts, rows.Err()
+}
+
+// FindPayout returns a payout with what it paid each rep
+func (r *CommissionRepository) FindPayout(ctx context.Context, orgID, id uuid.UUID) (*types.Payout, error) {
+	p, err := scanPayout(r.db.QueryRowContext(ctx, `
+		SELECT `+payoutColumns+` FROM commission_payouts WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("payout %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find payout: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+payoutLineColumns+`
+		FROM commission_entries e
+		LEFT JOIN auth.users u ON u.id = e.user_id
+		WHERE e.organization_id = $1 AND e.payout_id = $2
+		GROUP BY e.user_id, u.email
+		ORDER BY u.email, e.user_id
+	`, orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payout lines: %w", err)
+	}
+	if p.Lines, err = scanPayoutLines(rows); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,659 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RunInterval is how often scheduled accrual runs
+	RunInterval = time.Hour
+	// DefaultEntryLimit is the page size of the entry list
+	DefaultEntryLimit = 100
+	// MaxEntryLimit bounds the page size of the entry list
+	MaxEntryLimit = 500
+)
+
+var (
+	// ErrInvalid wraps validation failures of plans, splits, disputes and payouts
+	ErrInvalid = errors.New("invalid request")
+	// ErrInDispute is returned when disputing an entry that is already disputed
+	ErrInDispute = errors.New("commission entry already in dispute")
+	// ErrNotInDispute is returned when resolving an entry without an open dispute
+	ErrNotInDispute = errors.New("commission entry is not in dispute")
+)
+
+// AuthService defines the permission check used by the commission service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// CommissionService manages commission plans and deal splits, accrues
+// commissions on won leads and paid invoices, handles disputes and
+// adjustments, and pays entries out to payroll
+type CommissionService struct {
+	repo        repository.CommissionRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewCommissionService(repo repository.CommissionRepo, authService AuthService, logger *slog.Logger) *CommissionService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CommissionService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ListPlans returns the organization's commission plans
+func (s *CommissionService) ListPlans(ctx context.Context, orgID uuid.UUID) ([]types.Plan, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListPlans(ctx, orgID)
+}
+
+// GetPlan returns a commission plan
+func (s *CommissionService) GetPlan(ctx context.Context, orgID, id uuid.UUID) (*types.Plan, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindPlan(ctx, orgID, id)
+}
+
+// CreatePlan validates and creates a commission plan
+func (s *CommissionService) CreatePlan(ctx context.Context, orgID, userID uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validatePlan(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreatePlan(ctx, orgID, userID, request)
+}
+
+// UpdatePlan validates and replaces a commission plan. Entries already
+// accrued keep the amount computed under the previous terms.
+func (s *CommissionService) UpdatePlan(ctx context.Context, orgID, userID, id uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validatePlan(&request); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.repo.FindPlan(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	active := request.Active == nil || *request.Active
+	if active && (request.Basis != plan.Basis || !plan.Active) && len(plan.MemberIDs) > 0 {
+		// Members were only checked against the active plans of the old basis
+		if err := s.checkMembers(ctx, orgID, id, request.Basis, plan.MemberIDs); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.UpdatePlan(ctx, orgID, userID, id, request)
+}
+
+func validatePlan(request *types.PlanRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if !request.Basis.IsValid() {
+		return fmt.Errorf("%w: basis must be lead_won or invoice_paid", ErrInvalid)
+	}
+	if request.Rate < 0 || request.Rate > 100 {
+		return fmt.Errorf("%w: rate must be between 0 and 100", ErrInvalid)
+	}
+
+	switch request.Type {
+	case types.PlanTypeFlat:
+		if request.Rate == 0 {
+			return fmt.Errorf("%w: flat plans need a rate", ErrInvalid)
+		}
+		request.Tiers, request.ProductRates = nil, nil
+	case types.PlanTypeTiered:
+		if len(request.Tiers) == 0 {
+			return fmt.Errorf("%w: tiered plans need tiers", ErrInvalid)
+		}
+		sort.SliceStable(request.Tiers, func(i, j int) bool {
+			return request.Tiers[i].Threshold < request.Tiers[j].Threshold
+		})
+		if request.Tiers[0].Threshold != 0 {
+			return fmt.Errorf("%w: the first tier must start at 0", ErrInvalid)
+		}
+		for i, tier := range request.Tiers {
+			if tier.Rate < 0 || tier.Rate > 100 {
+				return fmt.Errorf("%w: tier rates must be between 0 and 100", ErrInvalid)
+			}
+			if i > 0 && tier.Threshold == request.Tiers[i-1].Threshold {
+				return fmt.Errorf("%w: tier thresholds must be distinct", ErrInvalid)
+			}
+		}
+		request.Rate, request.ProductRates = 0, nil
+	case types.PlanTypePerProduct:
+		if request.Basis != types.BasisInvoicePaid {
+			return fmt.Errorf("%w: per-product plans pay on invoices", ErrInvalid)
+		}
+		if len(request.ProductRates) == 0 {
+			return fmt.Errorf("%w: per-product plans need product rates", ErrInvalid)
+		}
+		seen := make(map[uuid.UUID]bool)
+		for _, rate := range request.ProductRates {
+			if rate.ProductID == uuid.Nil || seen[rate.ProductID] {
+				return fmt.Errorf("%w: product rates need distinct products", ErrInvalid)
+			}
+			if rate.Rate < 0 || rate.Rate > 100 {
+				return fmt.Errorf("%w: product rates must be between 0 and 100", ErrInvalid)
+			}
+			seen[rate.ProductID] = true
+		}
+		request.Tiers = nil
+	default:
+		return fmt.Errorf("%w: plan_type must be flat, tiered or per_product", ErrInvalid)
+	}
+	return nil
+}
+
+// SetPlanMembers replaces the reps on a plan. A rep is on at most one
+// active plan per basis.
+func (s *CommissionService) SetPlanMembers(ctx context.Context, orgID, planID uuid.UUID, request types.PlanMembersRequest) (*types.Plan, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	plan, err := s.repo.FindPlan(ctx, orgID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	userIDs := make([]uuid.UUID, 0, len(request.UserIDs))
+	for _, id := range request.UserIDs {
+		if id == uuid.Nil {
+			return nil, fmt.Errorf("%w: user_ids cannot contain a nil ID", ErrInvalid)
+		}
+		if !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	if err := s.checkMembers(ctx, orgID, planID, plan.Basis, userIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPlanMembers(ctx, orgID, planID, userIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPlan(ctx, orgID, planID)
+}
+
+// checkMembers fails when one of the reps is on another active plan of the basis
+func (s *CommissionService) checkMembers(ctx context.Context, orgID, planID uuid.UUID, basis types.Basis, userIDs []uuid.UUID) error {
+	plans, err := s.repo.ListPlans(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, other := range plans {
+		if other.ID == planID || !other.Active || other.Basis != basis {
+			continue
+		}
+		for _, id := range other.MemberIDs {
+			for _, userID := range userIDs {
+				if id == userID {
+					return fmt.Errorf("%w: user %s is already on plan %s", ErrInvalid, userID, other.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetSplits returns how a lead or invoice's credit is split. Deals without
+// splits credit their owner in full.
+func (s *CommissionService) GetSplits(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, sourceID uuid.UUID) (*types.DealSplits, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !sourceType.IsValid() {
+		return nil, fmt.Errorf("%w: source_type must be lead or invoice", ErrInvalid)
+	}
+
+	splits, err := s.repo.FindSplits(ctx, orgID, sourceType, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DealSplits{SourceType: sourceType, SourceID: sourceID, Splits: splits}, nil
+}
+
+// SaveSplits splits a team deal's credit between reps. Shares must add up to
+// 100; no splits credits the deal's owner again. Splits are fixed once the
+// deal has accrued; corrections are then adjustments.
+func (s *CommissionService) SaveSplits(ctx context.Context, orgID, userID uuid.UUID, request types.DealSplits) (*types.DealSplits, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !request.SourceType.IsValid() {
+		return nil, fmt.Errorf("%w: source_type must be lead or invoice", ErrInvalid)
+	}
+	if request.SourceID == uuid.Nil {
+		return nil, fmt.Errorf("%w: source_id is required", ErrInvalid)
+	}
+
+	var total float64
+	seen := make(map[uuid.UUID]bool)
+	for _, split := range request.Splits {
+		if split.UserID == uuid.Nil || seen[split.UserID] {
+			return nil, fmt.Errorf("%w: splits need distinct users", ErrInvalid)
+		}
+		if split.Share <= 0 || split.Share > 100 {
+			return nil, fmt.Errorf("%w: shares must be between 0 and 100", ErrInvalid)
+		}
+		seen[split.UserID] = true
+		total += split.Share
+	}
+	if len(request.Splits) > 0 && math.Abs(total-100) > 0.005 {
+		return nil, fmt.Errorf("%w: shares add up to %.2f, not 100", ErrInvalid, total)
+	}
+
+	if err := s.repo.SaveSplits(ctx, orgID, userID, request); err != nil {
+		return nil, err
+	}
+	if request.Splits == nil {
+		request.Splits = []types.Split{}
+	}
+	return &request, nil
+}
+
+// Accrue accrues commissions on the organization's won leads and paid
+// invoices that have not accrued yet
+func (s *CommissionService) Accrue(ctx context.Context, orgID, userID uuid.UUID) (*types.AccrualResult, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.accrue(ctx, orgID, &userID)
+}
+
+// AccrueAll accrues commissions for every organization with an active plan.
+// It is invoked by the scheduled job and therefore bypasses the per-request
+// permission check.
+func (s *CommissionService) AccrueAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := s.accrue(ctx, orgID, nil)
+		if err != nil {
+			failed++
+			s.logger.Error("Commission accrual failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if len(result.Entries) > 0 {
+			s.logger.Info("Accrued commissions", "organization_id", orgID, "entries", len(result.Entries), "amount", result.Amount)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("commission accrual failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *CommissionService) accrue(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID) (*types.AccrualResult, error) {
+	plans, err := s.repo.ListPlans(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.AccrualResult{Entries: []types.Entry{}}
+	for _, basis := range []types.Basis{types.BasisLeadWon, types.BasisInvoicePaid} {
+		planOf := make(map[uuid.UUID]types.Plan)
+		var since *time.Time
+		for _, plan := range plans {
+			if !plan.Active || plan.Basis != basis {
+				continue
+			}
+			for _, memberID := range plan.MemberIDs {
+				planOf[memberID] = plan
+			}
+			if since == nil || plan.EffectiveFrom.Before(*since) {
+				from := plan.EffectiveFrom
+				since = &from
+			}
+		}
+		if len(planOf) == 0 {
+			continue
+		}
+
+		sources, err := s.repo.PendingSources(ctx, orgID, basis.SourceType(), *since)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			result.Sources++
+			for _, credit := range credits(source) {
+				plan, ok := planOf[credit.UserID]
+				if !ok || source.EarnedOn.Before(plan.EffectiveFrom) {
+					continue
+				}
+				entry, err := s.accrueCredit(ctx, orgID, userID, plan, source, credit)
+				if err != nil {
+					return nil, fmt.Errorf("failed to accrue %s %s: %w", source.Type, source.ID, err)
+				}
+				if entry != nil {
+					result.Entries = append(result.Entries, *entry)
+					result.Amount = roundAmount(result.Amount + entry.Amount)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// credits returns the reps a deal credits: its splits, or its owner in full
+func credits(source types.Source) []types.Split {
+	if len(source.Splits) > 0 {
+		return source.Splits
+	}
+	if source.OwnerID == nil {
+		return nil
+	}
+	return []types.Split{{UserID: *source.OwnerID, Share: 100}}
+}
+
+func (s *CommissionService) accrueCredit(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, plan types.Plan, source types.Source, credit types.Split) (*types.Entry, error) {
+	var prior float64
+	if plan.Type == types.PlanTypeTiered {
+		monthStart := time.Date(source.EarnedOn.Year(), source.EarnedOn.Month(), 1, 0, 0, 0, 0, time.UTC)
+		var err error
+		prior, err = s.repo.PeriodBase(ctx, orgID, credit.UserID, plan.ID, monthStart, monthStart.AddDate(0, 1, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	base, amount := commission(plan, source, credit.Share, prior)
+	var rate float64
+	if base != 0 {
+		rate = math.Round(amount/base*100*10000) / 10000
+	}
+
+	sourceType, sourceID := source.Type, source.ID
+	planID := plan.ID
+	return s.repo.CreateEntry(ctx, types.Entry{
+		OrganizationID: orgID,
+		UserID:         credit.UserID,
+		PlanID:         &planID,
+		EntryType:      types.EntryAccrual,
+		SourceType:     &sourceType,
+		SourceID:       &sourceID,
+		SourceName:     source.Name,
+		EarnedOn:       source.EarnedOn,
+		BaseAmount:     base,
+		Share:          credit.Share,
+		Rate:           rate,
+		Amount:         amount,
+		CreatedBy:      userID,
+	})
+}
+
+// commission returns the rep's base, their share of the deal amount, and
+// the commission the plan pays on it. prior is the base the rep already
+// accrued on the plan in the month, which tiered plans build on.
+func commission(plan types.Plan, source types.Source, share, prior float64) (float64, float64) {
+	base := roundAmount(source.Amount * share / 100)
+
+	var amount float64
+	switch plan.Type {
+	case types.PlanTypeFlat:
+		amount = base * plan.Rate / 100
+	case types.PlanTypeTiered:
+		amount = tieredCommission(plan.Tiers, prior, prior+base)
+	case types.PlanTypePerProduct:
+		rates := make(map[uuid.UUID]float64, len(plan.ProductRates))
+		for _, r := range plan.ProductRates {
+			rates[r.ProductID] = r.Rate
+		}
+		if len(source.Lines) == 0 {
+			amount = base * plan.Rate / 100
+		}
+		for _, line := range source.Lines {
+			rate := plan.Rate
+			if line.ProductID != nil {
+				if r, ok := rates[*line.ProductID]; ok {
+					rate = r
+				}
+			}
+			amount += line.Amount * share / 100 * rate / 100
+		}
+	}
+	return base, roundAmount(amount)
+}
+
+// tieredCommission pays each tier's rate on the part of [from, to) within
+// the tier. Tiers are sorted by threshold.
+func tieredCommission(tiers []types.Tier, from, to float64) float64 {
+	var amount float64
+	for i, tier := range tiers {
+		upper := math.Inf(1)
+		if i+1 < len(tiers) {
+			upper = tiers[i+1].Threshold
+		}
+		lo, hi := math.Max(from, tier.Threshold), math.Min(to, upper)
+		if hi > lo {
+			amount += (hi - lo) * tier.Rate / 100
+		}
+	}
+	return amount
+}
+
+// ListEntries lists commission entries, newest first
+func (s *CommissionService) ListEntries(ctx context.Context, filter types.EntryFilter) ([]types.Entry, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultEntryLimit
+	}
+	if filter.Limit > MaxEntryLimit {
+		filter.Limit = MaxEntryLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListEntries(ctx, filter)
+}
+
+// GetEntry returns a commission entry
+func (s *CommissionService) GetEntry(ctx context.Context, orgID, id uuid.UUID) (*types.Entry, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindEntry(ctx, orgID, id)
+}
+
+// Dispute disputes an entry. Unpaid entries are held back from payouts
+// until the dispute is resolved.
+func (s *CommissionService) Dispute(ctx context.Context, orgID, userID, id uuid.UUID, request types.DisputeRequest) (*types.Entry, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:dispute"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalid)
+	}
+
+	entry, err := s.repo.FindEntry(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.InDispute() {
+		return nil, ErrInDispute
+	}
+
+	if err := s.repo.DisputeEntry(ctx, orgID, id, userID, reason, s.now()); err != nil {
+		return nil, err
+	}
+	return s.repo.FindEntry(ctx, orgID, id)
+}
+
+// Resolve closes a dispute. A non-zero adjustment is recorded as an
+// adjustment entry for the same rep, paid with the next payout.
+func (s *CommissionService) Resolve(ctx context.Context, orgID, userID, id uuid.UUID, request types.ResolveRequest) (*types.Entry, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	entry, err := s.repo.FindEntry(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.InDispute() {
+		return nil, ErrNotInDispute
+	}
+
+	now := s.now()
+	note := strings.TrimSpace(request.Note)
+	var adjustment *types.Entry
+	if amount := roundAmount(request.Adjustment); amount != 0 {
+		adjustment = s.adjustmentEntry(orgID, userID, entry.UserID, amount, note, now, &entry.ID)
+		adjustment.PlanID = entry.PlanID
+	}
+
+	if err := s.repo.ResolveDispute(ctx, orgID, id, userID, note, now, adjustment); err != nil {
+		return nil, err
+	}
+	return s.repo.FindEntry(ctx, orgID, id)
+}
+
+// Adjust records a manual adjustment for a rep, such as a clawback or a bonus
+func (s *CommissionService) Adjust(ctx context.Context, orgID, userID uuid.UUID, request types.AdjustmentRequest) (*types.Entry, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalid)
+	}
+	amount := roundAmount(request.Amount)
+	if amount == 0 {
+		return nil, fmt.Errorf("%w: amount is required", ErrInvalid)
+	}
+	note := strings.TrimSpace(request.Note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: note is required", ErrInvalid)
+	}
+
+	var planID *uuid.UUID
+	if request.EntryID != nil {
+		entry, err := s.repo.FindEntry(ctx, orgID, *request.EntryID)
+		if err != nil {
+			return nil, err
+		}
+		if entry.UserID != request.UserID {
+			return nil, fmt.Errorf("%w: the adjusted entry belongs to another user", ErrInvalid)
+		}
+		planID = entry.PlanID
+	}
+
+	earnedOn := s.now()
+	if request.EarnedOn != nil {
+		earnedOn = *request.EarnedOn
+	}
+	adjustment := s.adjustmentEntry(orgID, userID, request.UserID, amount, note, earnedOn, request.EntryID)
+	adjustment.PlanID = planID
+	return s.repo.CreateEntry(ctx, *adjustment)
+}
+
+func (s *CommissionService) adjustmentEntry(orgID, createdBy, repID uuid.UUID, amount float64, note string, earnedOn time.Time, adjusts *uuid.UUID) *types.Entry {
+	return &types.Entry{
+		OrganizationID: orgID,
+		UserID:         repID,
+		EntryType:      types.EntryAdjustment,
+		EarnedOn:       earnedOn,
+		Share:          100,
+		Amount:         amount,
+		AdjustsEntryID: adjusts,
+		Note:           note,
+		CreatedBy:      &createdBy,
+	}
+}
+
+// PayoutReport previews what each rep would be paid for the unpaid entries
+// earned up to periodEnd
+func (s *CommissionService) PayoutReport(ctx context.Context, orgID uuid.UUID, periodEnd time.Time) (*types.PayoutReport, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if periodEnd.IsZero() {
+		periodEnd = s.now()
+	}
+
+	lines, held, err := s.repo.PayoutReport(ctx, orgID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	report := &types.PayoutReport{PeriodEnd: periodEnd, Lines: lines, HeldEntries: held}
+	for _, line := range lines {
+		report.Total = roundAmount(report.Total + line.Total)
+	}
+	return report, nil
+}
+
+// CreatePayout marks the unpaid entries earned up to the period end as paid,
+// except those held back by open disputes
+func (s *CommissionService) CreatePayout(ctx context.Context, orgID, userID uuid.UUID, request types.PayoutRequest) (*types.Payout, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.PeriodEnd.IsZero() {
+		return nil, fmt.Errorf("%w: period_end is required", ErrInvalid)
+	}
+	if request.PeriodEnd.After(s.now()) {
+		return nil, fmt.Errorf("%w: period_end cannot be in the future", ErrInvalid)
+	}
+	return s.repo.CreatePayout(ctx, orgID, userID, request.PeriodEnd)
+}
+
+// ListPayouts lists the organization's payouts, latest first
+func (s *CommissionService) ListPayouts(ctx context.Context, orgID uuid.UUID) ([]types.Payout, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListPayouts(ctx, orgID)
+}
+
+// GetPayout returns a payout with what it paid each rep
+func (s *CommissionService) GetPayout(ctx context.Context, orgID, id uuid.UUID) (*types.Payout, error) {
+	if err := s.authService.CheckPermission(ctx, "commissions:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindPayout(ctx, orgID, id)
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
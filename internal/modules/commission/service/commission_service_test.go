@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/commission/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCommissionRepo struct {
+	plans   []types.Plan
+	sources map[types.SourceType][]types.Source
+	entries []types.Entry
+	splits  *types.DealSplits
+
+	resolvedWith *types.Entry
+}
+
+func newFakeCommissionRepo() *fakeCommissionRepo {
+	return &fakeCommissionRepo{sources: make(map[types.SourceType][]types.Source)}
+}
+
+func (f *fakeCommissionRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeCommissionRepo) ListPlans(ctx context.Context, orgID uuid.UUID) ([]types.Plan, error) {
+	return f.plans, nil
+}
+
+func (f *fakeCommissionRepo) FindPlan(ctx context.Context, orgID, id uuid.UUID) (*types.Plan, error) {
+	for i := range f.plans {
+		if f.plans[i].ID == id {
+			return &f.plans[i], nil
+		}
+	}
+	return nil, fmt.Errorf("commission plan %w", repository.ErrNotFound)
+}
+
+func (f *fakeCommissionRepo) CreatePlan(ctx context.Context, orgID, userID uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	plan := types.Plan{ID: uuid.New(), Name: request.Name, Basis: request.Basis, Type: request.Type, Rate: request.Rate, Tiers: request.Tiers}
+	f.plans = append(f.plans, plan)
+	return &plan, nil
+}
+
+func (f *fakeCommissionRepo) UpdatePlan(ctx context.Context, orgID, userID, id uuid.UUID, request types.PlanRequest) (*types.Plan, error) {
+	return f.FindPlan(ctx, orgID, id)
+}
+
+func (f *fakeCommissionRepo) SetPlanMembers(ctx context.Context, orgID, planID uuid.UUID, userIDs []uuid.UUID) error {
+	for i := range f.plans {
+		if f.plans[i].ID == planID {
+			f.plans[i].MemberIDs = userIDs
+		}
+	}
+	return nil
+}
+
+func (f *fakeCommissionRepo) FindSplits(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, sourceID uuid.UUID) ([]types.Split, error) {
+	return nil, nil
+}
+
+func (f *fakeCommissionRepo) SaveSplits(ctx context.Context, orgID, userID uuid.UUID, splits types.DealSplits) error {
+	f.splits = &splits
+	return nil
+}
+
+func (f *fakeCommissionRepo) PendingSources(ctx context.Context, orgID uuid.UUID, sourceType types.SourceType, since time.Time) ([]types.Source, error) {
+	var pending []types.Source
+	for _, s := range f.sources[sourceType] {
+		if !s.EarnedOn.Before(since) {
+			pending = append(pending, s)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeCommissionRepo) PeriodBase(ctx context.Context, orgID, userID, planID uuid.UUID, from, to time.Time) (float64, error) {
+	var base float64
+	for _, e := range f.entries {
+		if e.UserID == userID && e.PlanID != nil && *e.PlanID == planID && !e.EarnedOn.Before(from) && e.EarnedOn.Before(to) {
+			base += e.BaseAmount
+		}
+	}
+	return base, nil
+}
+
+func (f *fakeCommissionRepo) CreateEntry(ctx context.Context, entry types.Entry) (*types.Entry, error) {
+	entry.ID = uuid.New()
+	f.entries = append(f.entries, entry)
+	return &entry, nil
+}
+
+func (f *fakeCommissionRepo) ListEntries(ctx context.Context, filter types.EntryFilter) ([]types.Entry, error) {
+	return f.entries, nil
+}
+
+func (f *fakeCommissionRepo) FindEntry(ctx context.Context, orgID, id uuid.UUID) (*types.Entry, error) {
+	for i := range f.entries {
+		if f.entries[i].ID == id {
+			e := f.entries[i]
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("commission entry %w", repository.ErrNotFound)
+}
+
+func (f *fakeCommissionRepo) DisputeEntry(ctx context.Context, orgID, id, userID uuid.UUID, reason string, at time.Time) error {
+	for i := range f.entries {
+		if f.entries[i].ID == id {
+			f.entries[i].DisputeReason, f.entries[i].DisputedBy, f.entries[i].DisputedAt = reason, &userID, &at
+			f.entries[i].ResolvedAt = nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeCommissionRepo) ResolveDispute(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time, adjustment *types.Entry) error {
+	for i := range f.entries {
+		if f.entries[i].ID == id {
+			f.entries[i].ResolutionNote, f.entries[i].ResolvedAt = note, &at
+		}
+	}
+	f.resolvedWith = adjustment
+	return nil
+}
+
+func (f *fakeCommissionRepo) PayoutReport(ctx context.Context, orgID uuid.UUID, periodEnd time.Time) ([]types.PayoutLine, int, error) {
+	return nil, 0, nil
+}
+
+func (f *fakeCommissionRepo) CreatePayout(ctx context.Context, orgID, userID uuid.UUID, periodEnd time.Time) (*types.Payout, error) {
+	return &types.Payout{ID: uuid.New(), PeriodEnd: periodEnd}, nil
+}
+
+func (f *fakeCommissionRepo) ListPayouts(ctx context.Context, orgID uuid.UUID) ([]types.Payout, error) {
+	return nil, nil
+}
+
+func (f *fakeCommissionRepo) FindPayout(ctx context.Context, orgID, id uuid.UUID) (*types.Payout, error) {
+	return nil, fmt.Errorf("payout %w", repository.ErrNotFound)
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type denyAll struct{}
+
+func (denyAll) CheckPermission(ctx context.Context, permission string) error {
+	return errors.New("forbidden")
+}
+
+func newTestService(repo *fakeCommissionRepo) *CommissionService {
+	svc := NewCommissionService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestTieredCommission(t *testing.T) {
+	tiers := []types.Tier{{Threshold: 0, Rate: 5}, {Threshold: 10000, Rate: 8}, {Threshold: 50000, Rate: 10}}
+
+	assert.InDelta(t, 250, tieredCommission(tiers, 0, 5000), 0.001)
+	// 5,000 at 5% and 3,000 at 8%
+	assert.InDelta(t, 490, tieredCommission(tiers, 5000, 13000), 0.001)
+	// 40,000 at 8% and 10,000 at 10%
+	assert.InDelta(t, 4200, tieredCommission(tiers, 10000, 60000), 0.001)
+}
+
+func TestAccrue(t *testing.T) {
+	ctx := context.Background()
+	orgID, managerID := uuid.New(), uuid.New()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	widget := uuid.New()
+
+	t.Run("flat plan on won leads credits the owner", func(t *testing.T) {
+		repo := newFakeCommissionRepo()
+		repo.plans = []types.Plan{{
+			ID: uuid.New(), Name: "AE", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 10,
+			MemberIDs: []uuid.UUID{alice}, EffectiveFrom: day(1, 1), Active: true,
+		}}
+		repo.sources[types.SourceLead] = []types.Source{
+			{Type: types.SourceLead, ID: uuid.New(), Name: "Acme renewal", EarnedOn: day(3, 3), Amount: 12000, OwnerID: &alice},
+			{Type: types.SourceLead, ID: uuid.New(), Name: "No plan", EarnedOn: day(3, 4), Amount: 5000, OwnerID: &carol},
+		}
+
+		result, err := newTestService(repo).Accrue(ctx, orgID, managerID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Sources)
+		require.Len(t, result.Entries, 1)
+		entry := result.Entries[0]
+		assert.Equal(t, alice, entry.UserID)
+		assert.Equal(t, types.EntryAccrual, entry.EntryType)
+		assert.Equal(t, 12000.0, entry.BaseAmount)
+		assert.Equal(t, 1200.0, entry.Amount)
+		assert.Equal(t, 10.0, entry.Rate)
+		assert.Equal(t, 1200.0, result.Amount)
+	})
+
+	t.Run("splits team deals between reps", func(t *testing.T) {
+		repo := newFakeCommissionRepo()
+		repo.plans = []types.Plan{{
+			ID: uuid.New(), Name: "AE", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 10,
+			MemberIDs: []uuid.UUID{alice, bob}, EffectiveFrom: day(1, 1), Active: true,
+		}}
+		repo.sources[types.SourceLead] = []types.Source{{
+			Type: types.SourceLead, ID: uuid.New(), EarnedOn: day(3, 3), Amount: 10000, OwnerID: &alice,
+			Splits: []types.Split{{UserID: alice, Share: 60}, {UserID: bob, Share: 40}},
+		}}
+
+		result, err := newTestService(repo).Accrue(ctx, orgID, managerID)
+		require.NoError(t, err)
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, alice, result.Entries[0].UserID)
+		assert.Equal(t, 6000.0, result.Entries[0].BaseAmount)
+		assert.Equal(t, 600.0, result.Entries[0].Amount)
+		assert.Equal(t, bob, result.Entries[1].UserID)
+		assert.Equal(t, 400.0, result.Entries[1].Amount)
+	})
+
+	t.Run("tiered plan builds on the month's base", func(t *testing.T) {
+		planID := uuid.New()
+		repo := newFakeCommissionRepo()
+		repo.plans = []types.Plan{{
+			ID: planID, Name: "Tiered", Basis: types.BasisInvoicePaid, Type: types.PlanTypeTiered,
+			Tiers:     []types.Tier{{Threshold: 0, Rate: 5}, {Threshold: 10000, Rate: 8}},
+			MemberIDs: []uuid.UUID{alice}, EffectiveFrom: day(1, 1), Active: true,
+		}}
+		repo.sources[types.SourceInvoice] = []types.Source{
+			{Type: types.SourceInvoice, ID: uuid.New(), EarnedOn: day(2, 27), Amount: 9000, OwnerID: &alice},
+			{Type: types.SourceInvoice, ID: uuid.New(), EarnedOn: day(3, 2), Amount: 8000, OwnerID: &alice},
+			{Type: types.SourceInvoice, ID: uuid.New(), EarnedOn: day(3, 20), Amount: 5000, OwnerID: &alice},
+		}
+
+		result, err := newTestService(repo).Accrue(ctx, orgID, managerID)
+		require.NoError(t, err)
+		require.Len(t, result.Entries, 3)
+		assert.Equal(t, 450.0, result.Entries[0].Amount)
+		// A new month starts back at the first tier
+		assert.Equal(t, 400.0, result.Entries[1].Amount)
+		// 2,000 at 5% and 3,000 at 8%
+		assert.Equal(t, 340.0, result.Entries[2].Amount)
+		assert.Equal(t, 6.8, result.Entries[2].Rate)
+	})
+
+	t.Run("per-product plan pays each line's rate", func(t *testing.T) {
+		repo := newFakeCommissionRepo()
+		repo.plans = []types.Plan{{
+			ID: uuid.New(), Name: "Products", Basis: types.BasisInvoicePaid, Type: types.PlanTypePerProduct, Rate: 2,
+			ProductRates: []types.ProductRate{{ProductID: widget, Rate: 12}},
+			MemberIDs:    []uuid.UUID{alice}, EffectiveFrom: day(1, 1), Active: true,
+		}}
+		repo.sources[types.SourceInvoice] = []types.Source{{
+			Type: types.SourceInvoice, ID: uuid.New(), EarnedOn: day(3, 5), Amount: 1500, OwnerID: &alice,
+			Lines: []types.SourceLine{{ProductID: &widget, Amount: 1000}, {Amount: 500}},
+		}}
+
+		result, err := newTestService(repo).Accrue(ctx, orgID, managerID)
+		require.NoError(t, err)
+		require.Len(t, result.Entries, 1)
+		assert.Equal(t, 130.0, result.Entries[0].Amount)
+	})
+
+	t.Run("skips deals earned before the plan is effective", func(t *testing.T) {
+		repo := newFakeCommissionRepo()
+		repo.plans = []types.Plan{
+			{ID: uuid.New(), Name: "Old", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 5,
+				MemberIDs: []uuid.UUID{bob}, EffectiveFrom: day(1, 1), Active: true},
+			{ID: uuid.New(), Name: "New", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 10,
+				MemberIDs: []uuid.UUID{alice}, EffectiveFrom: day(3, 1), Active: true},
+		}
+		repo.sources[types.SourceLead] = []types.Source{
+			{Type: types.SourceLead, ID: uuid.New(), EarnedOn: day(2, 10), Amount: 1000, OwnerID: &alice},
+		}
+
+		result, err := newTestService(repo).Accrue(ctx, orgID, managerID)
+		require.NoError(t, err)
+		assert.Empty(t, result.Entries)
+	})
+
+	t.Run("requires permission", func(t *testing.T) {
+		svc := NewCommissionService(newFakeCommissionRepo(), denyAll{}, nil)
+		_, err := svc.Accrue(ctx, orgID, managerID)
+		assert.Error(t, err)
+	})
+}
+
+func TestCreatePlan(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+
+	invalid := map[string]types.PlanRequest{
+		"missing name":          {Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 5},
+		"unknown basis":         {Name: "X", Basis: "booked", Type: types.PlanTypeFlat, Rate: 5},
+		"flat without rate":     {Name: "X", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat},
+		"rate over 100":         {Name: "X", Basis: types.BasisLeadWon, Type: types.PlanTypeFlat, Rate: 150},
+		"tiers not from zero":   {Name: "X", Basis: types.BasisLeadWon, Type: types.PlanTypeTiered, Tiers: []types.Tier{{Threshold: 100, Rate: 5}}},
+		"per product on leads":  {Name: "X", Basis: types.BasisLeadWon, Type: types.PlanTypePerProduct, ProductRates: []types.ProductRate{{ProductID: uuid.New(), Rate: 5}}},
+		"per product no rates":  {Name: "X", Basis: types.BasisInvoicePaid, Type: types.PlanTypePerProduct},
+		"duplicate tier starts": {Name: "X", Basis: types.BasisLeadWon, Type: types.PlanTypeTiered, Tiers: []types.Tier{{Rate: 5}, {Rate: 6}}},
+	}
+	for name, req := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestService(newFakeCommissionRepo()).CreatePlan(ctx, orgID, userID, req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+
+	t.Run("sorts tiers", func(t *testing.T) {
+		repo := newFakeCommissionRepo()
+		plan, err := newTestService(repo).CreatePlan(ctx, orgID, userID, types.PlanRequest{
+			Name: " Tiered ", Basis: types.BasisInvoicePaid, Type: types.PlanTypeTiered,
+			Tiers: []types.Tier{{Threshold: 10000, Rate: 8}, {Threshold: 0, Rate: 5}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Tiered", plan.Name)
+		assert.Equal(t, 0.0, plan.Tiers[0].Threshold)
+	})
+}
+
+func TestSetPlanMembers(t *testing.T) {
+	ctx := context.Background()
+	orgID, alice := uuid.New(), uuid.New()
+	repo := newFakeCommissionRepo()
+	repo.plans = []types.Plan{
+		{ID: uuid.New(), Name: "AE", Basis: types.BasisLeadWon, Active: true, MemberIDs: []uuid.UUID{alice}},
+		{ID: uuid.New(), Name: "Invoices", Basis: types.BasisInvoicePaid, Active: true},
+		{ID: uuid.New(), Name: "SDR", Basis: types.BasisLeadWon, Active: true},
+	}
+	svc := newTestService(repo)
+
+	plan, err := svc.SetPlanMembers(ctx, orgID, repo.plans[1].ID, types.PlanMembersRequest{UserIDs: []uuid.UUID{alice, alice}})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{alice}, plan.MemberIDs)
+
+	_, err = svc.SetPlanMembers(ctx, orgID, repo.plans[2].ID, types.PlanMembersRequest{UserIDs: []uuid.UUID{alice}})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestSaveSplits(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	alice, bob := uuid.New(), uuid.New()
+	leadID := uuid.New()
+
+	repo := newFakeCommissionRepo()
+	svc := newTestService(repo)
+
+	_, err := svc.SaveSplits(ctx, orgID, userID, types.DealSplits{
+		SourceType: types.SourceLead, SourceID: leadID,
+		Splits: []types.Split{{UserID: alice, Share: 50}, {UserID: bob, Share: 40}},
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.SaveSplits(ctx, orgID, userID, types.DealSplits{
+		SourceType: types.SourceLead, SourceID: leadID,
+		Splits: []types.Split{{UserID: alice, Share: 50}, {UserID: alice, Share: 50}},
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	splits, err := svc.SaveSplits(ctx, orgID, userID, types.DealSplits{
+		SourceType: types.SourceLead, SourceID: leadID,
+		Splits: []types.Split{{UserID: alice, Share: 66.67}, {UserID: bob, Share: 33.33}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, splits.Splits, 2)
+	require.NotNil(t, repo.splits)
+
+	splits, err = svc.SaveSplits(ctx, orgID, userID, types.DealSplits{SourceType: types.SourceLead, SourceID: leadID})
+	require.NoError(t, err)
+	assert.Empty(t, splits.Splits)
+}
+
+func TestDisputeWorkflow(t *testing.T) {
+	ctx := context.Background()
+	orgID, rep, manager := uuid.New(), uuid.New(), uuid.New()
+	planID := uuid.New()
+
+	repo := newFakeCommissionRepo()
+	entry, _ := repo.CreateEntry(ctx, types.Entry{UserID: rep, PlanID: &planID, EntryType: types.EntryAccrual, Amount: 500})
+	svc := newTestService(repo)
+
+	_, err := svc.Resolve(ctx, orgID, manager, entry.ID, types.ResolveRequest{})
+	assert.ErrorIs(t, err, ErrNotInDispute)
+
+	_, err = svc.Dispute(ctx, orgID, rep, entry.ID, types.DisputeRequest{Reason: "  "})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	disputed, err := svc.Dispute(ctx, orgID, rep, entry.ID, types.DisputeRequest{Reason: "Deal was split 50/50"})
+	require.NoError(t, err)
+	assert.True(t, disputed.InDispute())
+
+	_, err = svc.Dispute(ctx, orgID, rep, entry.ID, types.DisputeRequest{Reason: "Again"})
+	assert.ErrorIs(t, err, ErrInDispute)
+
+	resolved, err := svc.Resolve(ctx, orgID, manager, entry.ID, types.ResolveRequest{Adjustment: -250, Note: "Split with Bob"})
+	require.NoError(t, err)
+	assert.False(t, resolved.InDispute())
+	require.NotNil(t, repo.resolvedWith)
+	assert.Equal(t, types.EntryAdjustment, repo.resolvedWith.EntryType)
+	assert.Equal(t, rep, repo.resolvedWith.UserID)
+	assert.Equal(t, -250.0, repo.resolvedWith.Amount)
+	assert.Equal(t, &entry.ID, repo.resolvedWith.AdjustsEntryID)
+	assert.Equal(t, &planID, repo.resolvedWith.PlanID)
+}
+
+func TestAdjust(t *testing.T) {
+	ctx := context.Background()
+	orgID, manager, rep := uuid.New(), uuid.New(), uuid.New()
+	repo := newFakeCommissionRepo()
+	svc := newTestService(repo)
+
+	_, err := svc.Adjust(ctx, orgID, manager, types.AdjustmentRequest{UserID: rep, Amount: 100})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	other, _ := repo.CreateEntry(ctx, types.Entry{UserID: uuid.New(), EntryType: types.EntryAccrual, Amount: 10})
+	_, err = svc.Adjust(ctx, orgID, manager, types.AdjustmentRequest{UserID: rep, Amount: 100, Note: "Bonus", EntryID: &other.ID})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	entry, err := svc.Adjust(ctx, orgID, manager, types.AdjustmentRequest{UserID: rep, Amount: 99.999, Note: "SPIFF"})
+	require.NoError(t, err)
+	assert.Equal(t, types.EntryAdjustment, entry.EntryType)
+	assert.Equal(t, 100.0, entry.Amount)
+	assert.Equal(t, day(3, 31).Add(18*time.Hour), entry.EarnedOn)
+}
+
+func TestCreatePayout(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(newFakeCommissionRepo())
+
+	_, err := svc.CreatePayout(ctx, uuid.New(), uuid.New(), types.PayoutRequest{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.CreatePayout(ctx, uuid.New(), uuid.New(), types.PayoutRequest{PeriodEnd: day(4, 30)})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	payout, err := svc.CreatePayout(ctx, uuid.New(), uuid.New(), types.PayoutRequest{PeriodEnd: day(3, 31)})
+	require.NoError(t, err)
+	assert.Equal(t, day(3, 31), payout.PeriodEnd)
+}
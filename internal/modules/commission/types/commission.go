@@ -0,0 +1,265 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Basis is the event a commission plan pays on
+type Basis string
+
+const (
+	// BasisLeadWon pays on the expected revenue of won leads
+	BasisLeadWon Basis = "lead_won"
+	// BasisInvoicePaid pays on the untaxed amount of paid customer invoices
+	BasisInvoicePaid Basis = "invoice_paid"
+)
+
+// IsValid reports whether the basis is supported
+func (b Basis) IsValid() bool {
+	return b == BasisLeadWon || b == BasisInvoicePaid
+}
+
+// SourceType returns the type of deal the basis accrues on
+func (b Basis) SourceType() SourceType {
+	if b == BasisLeadWon {
+		return SourceLead
+	}
+	return SourceInvoice
+}
+
+// PlanType is how a plan computes commissions
+type PlanType string
+
+const (
+	// PlanTypeFlat pays Rate percent of the base
+	PlanTypeFlat PlanType = "flat"
+	// PlanTypeTiered pays each tier's rate on the part of the rep's monthly
+	// base within the tier
+	PlanTypeTiered PlanType = "tiered"
+	// PlanTypePerProduct pays the product's rate on each invoice line, or
+	// Rate for products without one
+	PlanTypePerProduct PlanType = "per_product"
+)
+
+// IsValid reports whether the plan type is supported
+func (t PlanType) IsValid() bool {
+	return t == PlanTypeFlat || t == PlanTypeTiered || t == PlanTypePerProduct
+}
+
+// Tier is a band of a tiered plan, from Threshold up to the next tier
+type Tier struct {
+	Threshold float64 `json:"threshold"`
+	Rate      float64 `json:"rate"`
+}
+
+// ProductRate is a per-product plan's rate for a product
+type ProductRate struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Rate      float64   `json:"rate"`
+}
+
+// Plan is a commission plan and the reps on it
+type Plan struct {
+	ID             uuid.UUID     `json:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id"`
+	Name           string        `json:"name"`
+	Description    string        `json:"description,omitempty"`
+	Basis          Basis         `json:"basis"`
+	Type           PlanType      `json:"plan_type"`
+	Rate           float64       `json:"rate"`
+	Tiers          []Tier        `json:"tiers"`
+	ProductRates   []ProductRate `json:"product_rates"`
+	MemberIDs      []uuid.UUID   `json:"member_ids"`
+	// EffectiveFrom is the first day deals accrue on the plan
+	EffectiveFrom time.Time `json:"effective_from"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PlanRequest creates or replaces a commission plan
+type PlanRequest struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	Basis         Basis         `json:"basis"`
+	Type          PlanType      `json:"plan_type"`
+	Rate          float64       `json:"rate"`
+	Tiers         []Tier        `json:"tiers"`
+	ProductRates  []ProductRate `json:"product_rates"`
+	EffectiveFrom *time.Time    `json:"effective_from"`
+	Active        *bool         `json:"active"`
+}
+
+// PlanMembersRequest replaces the reps on a plan
+type PlanMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+// SourceType is the kind of deal a commission is earned on
+type SourceType string
+
+const (
+	SourceLead    SourceType = "lead"
+	SourceInvoice SourceType = "invoice"
+)
+
+// IsValid reports whether the source type is supported
+func (t SourceType) IsValid() bool {
+	return t == SourceLead || t == SourceInvoice
+}
+
+// Split is a rep's share, in percent, of a deal's credit
+type Split struct {
+	UserID uuid.UUID `json:"user_id"`
+	Share  float64   `json:"share"`
+}
+
+// DealSplits is how a team deal's credit is split between reps
+type DealSplits struct {
+	SourceType SourceType `json:"source_type"`
+	SourceID   uuid.UUID  `json:"source_id"`
+	Splits     []Split    `json:"splits"`
+}
+
+// SourceLine is an invoice line a per-product plan pays on
+type SourceLine struct {
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Amount    float64    `json:"amount"`
+}
+
+// Source is a won lead or paid invoice that has not accrued commissions yet
+type Source struct {
+	Type     SourceType `json:"source_type"`
+	ID       uuid.UUID  `json:"source_id"`
+	Name     string     `json:"name"`
+	EarnedOn time.Time  `json:"earned_on"`
+	Amount   float64    `json:"amount"`
+	// OwnerID is the lead's assignee or the invoice's salesperson, credited
+	// when the deal has no splits
+	OwnerID *uuid.UUID   `json:"owner_id,omitempty"`
+	Lines   []SourceLine `json:"lines,omitempty"`
+	Splits  []Split      `json:"splits,omitempty"`
+}
+
+// EntryType tells accruals from adjustments
+type EntryType string
+
+const (
+	EntryAccrual    EntryType = "accrual"
+	EntryAdjustment EntryType = "adjustment"
+)
+
+// Entry is a commission accrued or adjusted for a rep
+type Entry struct {
+	ID             uuid.UUID   `json:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	PlanID         *uuid.UUID  `json:"plan_id,omitempty"`
+	EntryType      EntryType   `json:"entry_type"`
+	SourceType     *SourceType `json:"source_type,omitempty"`
+	SourceID       *uuid.UUID  `json:"source_id,omitempty"`
+	SourceName     string      `json:"source_name,omitempty"`
+	EarnedOn       time.Time   `json:"earned_on"`
+	BaseAmount     float64     `json:"base_amount"`
+	Share          float64     `json:"share"`
+	// Rate is the effective rate, in percent, of the base
+	Rate           float64    `json:"rate"`
+	Amount         float64    `json:"amount"`
+	AdjustsEntryID *uuid.UUID `json:"adjusts_entry_id,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	DisputeReason  string     `json:"dispute_reason,omitempty"`
+	DisputedBy     *uuid.UUID `json:"disputed_by,omitempty"`
+	DisputedAt     *time.Time `json:"disputed_at,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	ResolvedBy     *uuid.UUID `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	PayoutID       *uuid.UUID `json:"payout_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+}
+
+// InDispute reports whether the entry has an open dispute
+func (e Entry) InDispute() bool {
+	return e.DisputedAt != nil && e.ResolvedAt == nil
+}
+
+// EntryFilter selects commission entries
+type EntryFilter struct {
+	OrganizationID uuid.UUID
+	UserID         *uuid.UUID
+	PayoutID       *uuid.UUID
+	Unpaid         bool
+	InDispute      bool
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// DisputeRequest disputes an entry
+type DisputeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ResolveRequest closes a dispute, adding an adjustment entry unless
+// Adjustment is zero
+type ResolveRequest struct {
+	Adjustment float64 `json:"adjustment"`
+	Note       string  `json:"note"`
+}
+
+// AdjustmentRequest adds a manual adjustment for a rep
+type AdjustmentRequest struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	Amount   float64    `json:"amount"`
+	Note     string     `json:"note"`
+	EarnedOn *time.Time `json:"earned_on"`
+	// EntryID is the entry the adjustment corrects, if any
+	EntryID *uuid.UUID `json:"entry_id"`
+}
+
+// AccrualResult summarizes an accrual run
+type AccrualResult struct {
+	Sources int     `json:"sources"`
+	Entries []Entry `json:"entries"`
+	Amount  float64 `json:"amount"`
+}
+
+// PayoutLine is what a rep is paid in a payout
+type PayoutLine struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email,omitempty"`
+	Entries     int       `json:"entries"`
+	Accrued     float64   `json:"accrued"`
+	Adjustments float64   `json:"adjustments"`
+	Total       float64   `json:"total"`
+}
+
+// PayoutReport lists what each rep would be paid for the unpaid entries
+// earned up to PeriodEnd
+type PayoutReport struct {
+	PeriodEnd time.Time    `json:"period_end"`
+	Lines     []PayoutLine `json:"lines"`
+	Total     float64      `json:"total"`
+	// HeldEntries counts the entries held back by open disputes
+	HeldEntries int `json:"held_entries"`
+}
+
+// PayoutRequest pays the unpaid entries earned up to PeriodEnd
+type PayoutRequest struct {
+	PeriodEnd time.Time `json:"period_end"`
+}
+
+// Payout is a batch of entries exported to payroll
+type Payout struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	PeriodEnd      time.Time    `json:"period_end"`
+	Total          float64      `json:"total"`
+	EntryCount     int          `json:"entry_count"`
+	Lines          []PayoutLine `json:"lines,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	CreatedBy      *uuid.UUID   `json:"created_by,omitempty"`
+}
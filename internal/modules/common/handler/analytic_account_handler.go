@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// AnalyticAccountHandler handles HTTP requests for analytic accounts
+type AnalyticAccountHandler struct {
+	service *service.AnalyticAccountService
+}
+
+func NewAnalyticAccountHandler(service *service.AnalyticAccountService) *AnalyticAccountHandler {
+	return &AnalyticAccountHandler{service: service}
+}
+
+func (h *AnalyticAccountHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/analytic-accounts", h.CreateAnalyticAccount)
+	router.GET("/api/v1/analytic-accounts/:id", h.GetAnalyticAccount)
+	router.GET("/api/v1/analytic-accounts", h.ListAnalyticAccounts)
+	router.PUT("/api/v1/analytic-accounts/:id", h.UpdateAnalyticAccount)
+	router.DELETE("/api/v1/analytic-accounts/:id", h.DeleteAnalyticAccount)
+}
+
+func (h *AnalyticAccountHandler) CreateAnalyticAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.AnalyticAccountCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func (h *AnalyticAccountHandler) GetAnalyticAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid analytic account ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if account == nil {
+		http.Error(w, "analytic account not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func (h *AnalyticAccountHandler) ListAnalyticAccounts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.AnalyticAccountFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.CompanyID, err = queryUUID(r, "company_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Active, err = queryBool(r, "active"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if code := r.URL.Query().Get("code"); code != "" {
+		filter.Code = &code
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+func (h *AnalyticAccountHandler) UpdateAnalyticAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid analytic account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.AnalyticAccountUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func (h *AnalyticAccountHandler) DeleteAnalyticAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid analytic account ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
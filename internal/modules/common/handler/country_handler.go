@@ -1,12 +1,12 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 )
@@ -26,7 +26,7 @@ func (h *CountryHandler) RegisterRoutes(router *httprouter.Router) {
 	router.GET("/api/v1/countries", h.ListCountries)
 	router.PUT("/api/v1/countries/:id", h.UpdateCountry)
 	router.DELETE("/api/v1/countries/:id", h.DeleteCountry)
-	router.GET("/api/v1/countries/code/:code", h.GetCountryByCode)
+	router.GET("/api/v1/countries-by-code/:code", h.GetCountryByCode)
 	router.GET("/api/v1/countries/:id/states", h.GetCountryWithStates)
 }
 
@@ -72,6 +72,8 @@ func (h *CountryHandler) GetCountry(w http.ResponseWriter, r *http.Request, ps h
 	json.NewEncoder(w).Encode(country)
 }
 
+// GetCountryByCode looks a country up by its ISO 3166-1 alpha-2, alpha-3 or
+// numeric code
 func (h *CountryHandler) GetCountryByCode(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := r.Context()
 
@@ -100,6 +102,13 @@ func (h *CountryHandler) ListCountries(w http.ResponseWriter, r *http.Request, _
 
 	// Parse query parameters
 	filter := types.CountryFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
 	if code := r.URL.Query().Get("code"); code != "" {
 		filter.Code = &code
 	}
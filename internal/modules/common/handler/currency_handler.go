@@ -6,6 +6,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 
 	"github.com/julienschmidt/httprouter"
@@ -101,6 +102,13 @@ func (h *CurrencyHandler) ListCurrencies(w http.ResponseWriter, r *http.Request,
 
 	// Parse query parameters
 	filter := types.CurrencyFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
 	if active := r.URL.Query().Get("active"); active != "" {
 		activeBool := active == "true"
 		filter.Active = &activeBool
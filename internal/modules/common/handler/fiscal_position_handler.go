@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// FiscalPositionHandler handles HTTP requests for fiscal positions
+type FiscalPositionHandler struct {
+	service *service.FiscalPositionService
+}
+
+func NewFiscalPositionHandler(service *service.FiscalPositionService) *FiscalPositionHandler {
+	return &FiscalPositionHandler{service: service}
+}
+
+func (h *FiscalPositionHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/fiscal-positions", h.CreateFiscalPosition)
+	router.GET("/api/v1/fiscal-positions/:id", h.GetFiscalPosition)
+	router.GET("/api/v1/fiscal-positions", h.ListFiscalPositions)
+	router.PUT("/api/v1/fiscal-positions/:id", h.UpdateFiscalPosition)
+	router.DELETE("/api/v1/fiscal-positions/:id", h.DeleteFiscalPosition)
+}
+
+func (h *FiscalPositionHandler) CreateFiscalPosition(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.FiscalPositionCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	position, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+func (h *FiscalPositionHandler) GetFiscalPosition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid fiscal position ID", http.StatusBadRequest)
+		return
+	}
+
+	position, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if position == nil {
+		http.Error(w, "fiscal position not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+func (h *FiscalPositionHandler) ListFiscalPositions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.FiscalPositionFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.CompanyID, err = queryUUID(r, "company_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.CountryID, err = queryUUID(r, "country_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Active, err = queryBool(r, "active"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	positions, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(positions)
+}
+
+func (h *FiscalPositionHandler) UpdateFiscalPosition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid fiscal position ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.FiscalPositionUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	position, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+func (h *FiscalPositionHandler) DeleteFiscalPosition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid fiscal position ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// IndustryHandler handles HTTP requests for industries
+type IndustryHandler struct {
+	service *service.IndustryService
+}
+
+func NewIndustryHandler(service *service.IndustryService) *IndustryHandler {
+	return &IndustryHandler{service: service}
+}
+
+func (h *IndustryHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/industries", h.CreateIndustry)
+	router.GET("/api/v1/industries/:id", h.GetIndustry)
+	router.GET("/api/v1/industries", h.ListIndustries)
+	router.PUT("/api/v1/industries/:id", h.UpdateIndustry)
+	router.DELETE("/api/v1/industries/:id", h.DeleteIndustry)
+}
+
+func (h *IndustryHandler) CreateIndustry(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.IndustryCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	industry, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(industry)
+}
+
+func (h *IndustryHandler) GetIndustry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid industry ID", http.StatusBadRequest)
+		return
+	}
+
+	industry, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if industry == nil {
+		http.Error(w, "industry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(industry)
+}
+
+func (h *IndustryHandler) ListIndustries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.IndustryFilter{}
+
+	var err error
+	if code := r.URL.Query().Get("code"); code != "" {
+		filter.Code = &code
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	industries, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(industries)
+}
+
+func (h *IndustryHandler) UpdateIndustry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid industry ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.IndustryUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	industry, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(industry)
+}
+
+func (h *IndustryHandler) DeleteIndustry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid industry ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
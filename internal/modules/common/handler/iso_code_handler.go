@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ISOCodeHandler handles HTTP requests mapping country and state ISO codes
+// to IDs and back
+type ISOCodeHandler struct {
+	service *service.ISOCodeService
+}
+
+func NewISOCodeHandler(service *service.ISOCodeService) *ISOCodeHandler {
+	return &ISOCodeHandler{service: service}
+}
+
+func (h *ISOCodeHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/iso-codes/resolve", h.Resolve)
+	router.POST("/api/v1/iso-codes", h.Codes)
+}
+
+// writeISOCodeError reports oversized batches as bad requests
+func writeISOCodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, types.ErrTooManyISOCodes) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// Resolve maps the country and state codes or names of an import to IDs
+func (h *ISOCodeHandler) Resolve(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.ISOCodeResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Resolve(ctx, req)
+	if err != nil {
+		writeISOCodeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Codes maps country and state IDs to their ISO codes for export
+func (h *ISOCodeHandler) Codes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.ISOCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Codes(ctx, req)
+	if err != nil {
+		writeISOCodeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PaymentTermHandler handles HTTP requests for payment terms
+type PaymentTermHandler struct {
+	service *service.PaymentTermService
+}
+
+func NewPaymentTermHandler(service *service.PaymentTermService) *PaymentTermHandler {
+	return &PaymentTermHandler{service: service}
+}
+
+func (h *PaymentTermHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/payment-terms", h.CreatePaymentTerm)
+	router.GET("/api/v1/payment-terms/:id", h.GetPaymentTerm)
+	router.GET("/api/v1/payment-terms", h.ListPaymentTerms)
+	router.PUT("/api/v1/payment-terms/:id", h.UpdatePaymentTerm)
+	router.DELETE("/api/v1/payment-terms/:id", h.DeletePaymentTerm)
+}
+
+func (h *PaymentTermHandler) CreatePaymentTerm(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.PaymentTermCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	term, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *PaymentTermHandler) GetPaymentTerm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid payment term ID", http.StatusBadRequest)
+		return
+	}
+
+	term, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if term == nil {
+		http.Error(w, "payment term not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *PaymentTermHandler) ListPaymentTerms(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.PaymentTermFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.CompanyID, err = queryUUID(r, "company_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Active, err = queryBool(r, "active"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	terms, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(terms)
+}
+
+func (h *PaymentTermHandler) UpdatePaymentTerm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid payment term ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PaymentTermUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	term, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *PaymentTermHandler) DeletePaymentTerm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid payment term ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
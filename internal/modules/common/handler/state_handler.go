@@ -6,6 +6,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 
 	"github.com/julienschmidt/httprouter"
@@ -26,7 +27,7 @@ func (h *StateHandler) RegisterRoutes(router *httprouter.Router) {
 	router.GET("/api/v1/states", h.ListStates)
 	router.PUT("/api/v1/states/:id", h.UpdateState)
 	router.DELETE("/api/v1/states/:id", h.DeleteState)
-	router.GET("/api/v1/countries/:country_id/states", h.ListStatesByCountry)
+	router.GET("/api/v1/states-by-country/:country_id", h.ListStatesByCountry)
 }
 
 func (h *StateHandler) CreateState(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -76,6 +77,13 @@ func (h *StateHandler) ListStates(w http.ResponseWriter, r *http.Request, _ http
 
 	// Parse query parameters
 	filter := types.StateFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
 	if countryID := r.URL.Query().Get("country_id"); countryID != "" {
 		parsedID, err := uuid.Parse(countryID)
 		if err != nil {
@@ -90,6 +98,22 @@ func (h *StateHandler) ListStates(w http.ResponseWriter, r *http.Request, _ http
 	if name := r.URL.Query().Get("name"); name != "" {
 		filter.Name = &name
 	}
+	if isoCode := r.URL.Query().Get("iso_code"); isoCode != "" {
+		filter.ISOCode = &isoCode
+	}
+	if subdivisionType := r.URL.Query().Get("type"); subdivisionType != "" {
+		filter.Type = &subdivisionType
+	}
+	if filter.ParentID, err = queryUUID(r, "parent_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	topLevel, err := queryBool(r, "top_level")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.TopLevel = topLevel != nil && *topLevel
 	if limit := r.URL.Query().Get("limit"); limit != "" {
 		// Parse limit
 		filter.Limit = 100 // default
@@ -6,6 +6,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 )
@@ -75,6 +76,13 @@ func (h *UOMCategoryHandler) ListUOMCategories(w http.ResponseWriter, r *http.Re
 
 	// Parse query parameters
 	filter := types.UOMCategoryFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
 	if name := r.URL.Query().Get("name"); name != "" {
 		filter.Name = &name
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 
 	"github.com/julienschmidt/httprouter"
@@ -77,6 +78,13 @@ func (h *UOMUnitHandler) ListUOMUnits(w http.ResponseWriter, r *http.Request, _
 
 	// Parse query parameters
 	filter := types.UOMUnitFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
 	if categoryID := r.URL.Query().Get("category_id"); categoryID != "" {
 		parsedID, err := uuid.Parse(categoryID)
 		if err != nil {
@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// UTMCampaignHandler handles HTTP requests for UTM campaigns
+type UTMCampaignHandler struct {
+	service *service.UTMCampaignService
+}
+
+func NewUTMCampaignHandler(service *service.UTMCampaignService) *UTMCampaignHandler {
+	return &UTMCampaignHandler{service: service}
+}
+
+func (h *UTMCampaignHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/utm/campaigns", h.CreateCampaign)
+	router.GET("/api/v1/utm/campaigns/:id", h.GetCampaign)
+	router.GET("/api/v1/utm/campaigns", h.ListCampaigns)
+	router.PUT("/api/v1/utm/campaigns/:id", h.UpdateCampaign)
+	router.DELETE("/api/v1/utm/campaigns/:id", h.DeleteCampaign)
+}
+
+func (h *UTMCampaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.UTMCampaignCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *UTMCampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if campaign == nil {
+		http.Error(w, "UTM campaign not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *UTMCampaignHandler) ListCampaigns(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.UTMCampaignFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	campaigns, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaigns)
+}
+
+func (h *UTMCampaignHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UTMCampaignUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *UTMCampaignHandler) DeleteCampaign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UTMMediumHandler handles HTTP requests for UTM mediums
+type UTMMediumHandler struct {
+	service *service.UTMMediumService
+}
+
+func NewUTMMediumHandler(service *service.UTMMediumService) *UTMMediumHandler {
+	return &UTMMediumHandler{service: service}
+}
+
+func (h *UTMMediumHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/utm/mediums", h.CreateMedium)
+	router.GET("/api/v1/utm/mediums/:id", h.GetMedium)
+	router.GET("/api/v1/utm/mediums", h.ListMediums)
+	router.PUT("/api/v1/utm/mediums/:id", h.UpdateMedium)
+	router.DELETE("/api/v1/utm/mediums/:id", h.DeleteMedium)
+}
+
+func (h *UTMMediumHandler) CreateMedium(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.UTMMediumCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	medium, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(medium)
+}
+
+func (h *UTMMediumHandler) GetMedium(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM medium ID", http.StatusBadRequest)
+		return
+	}
+
+	medium, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if medium == nil {
+		http.Error(w, "UTM medium not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(medium)
+}
+
+func (h *UTMMediumHandler) ListMediums(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.UTMMediumFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediums, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mediums)
+}
+
+func (h *UTMMediumHandler) UpdateMedium(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM medium ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UTMMediumUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	medium, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(medium)
+}
+
+func (h *UTMMediumHandler) DeleteMedium(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM medium ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UTMSourceHandler handles HTTP requests for UTM sources
+type UTMSourceHandler struct {
+	service *service.UTMSourceService
+}
+
+func NewUTMSourceHandler(service *service.UTMSourceService) *UTMSourceHandler {
+	return &UTMSourceHandler{service: service}
+}
+
+func (h *UTMSourceHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/utm/sources", h.CreateSource)
+	router.GET("/api/v1/utm/sources/:id", h.GetSource)
+	router.GET("/api/v1/utm/sources", h.ListSources)
+	router.PUT("/api/v1/utm/sources/:id", h.UpdateSource)
+	router.DELETE("/api/v1/utm/sources/:id", h.DeleteSource)
+}
+
+func (h *UTMSourceHandler) CreateSource(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var req types.UTMSourceCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := h.service.Create(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+func (h *UTMSourceHandler) GetSource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM source ID", http.StatusBadRequest)
+		return
+	}
+
+	source, err := h.service.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if source == nil {
+		http.Error(w, "UTM source not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+func (h *UTMSourceHandler) ListSources(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	// Parse query parameters
+	filter := types.UTMSourceFilter{}
+
+	var err error
+	if filter.OrganizationID, err = queryUUID(r, "organization_id"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if filter.Limit, filter.Offset, err = queryPage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sources, err := h.service.List(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sources)
+}
+
+func (h *UTMSourceHandler) UpdateSource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM source ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UTMSourceUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := h.service.Update(ctx, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
+
+func (h *UTMSourceHandler) DeleteSource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "invalid UTM source ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(ctx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -8,15 +8,18 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/service"
 	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
 	"github.com/julienschmidt/httprouter"
 )
 
 // CommonModule represents the common module for foundation/reference data
 type CommonModule struct {
+	attachmentRepo         repository.AttachmentRepository
 	attachmentHandler      *handler.AttachmentHandler
 	currencyHandler        *handler.CurrencyHandler
 	countryHandler         *handler.CountryHandler
 	stateHandler           *handler.StateHandler
+	isoCodeHandler         *handler.ISOCodeHandler
 	uomCategoryHandler     *handler.UOMCategoryHandler
 	uomUnitHandler         *handler.UOMUnitHandler
 	paymentTermHandler     *handler.PaymentTermHandler
@@ -46,7 +49,7 @@ func (m *CommonModule) Init(ctx context.Context, deps registry.Dependencies) err
 	m.logger.Info("Initializing common module")
 
 	// Create repositories
-	attachmentRepo := repository.NewAttachmentRepository(deps.DB)
+	m.attachmentRepo = repository.NewAttachmentRepository(deps.DB)
 	currencyRepo := repository.NewCurrencyRepository(deps.DB)
 	countryRepo := repository.NewCountryRepository(deps.DB)
 	stateRepo := repository.NewStateRepository(deps.DB)
@@ -61,10 +64,10 @@ func (m *CommonModule) Init(ctx context.Context, deps registry.Dependencies) err
 	utmSourceRepo := repository.NewUTMSourceRepository(deps.DB)
 
 	// Create services
-	attachmentService := service.NewAttachmentService(attachmentRepo)
 	currencyService := service.NewCurrencyService(currencyRepo)
 	countryService := service.NewCountryService(countryRepo)
 	stateService := service.NewStateService(stateRepo)
+	isoCodeService := service.NewISOCodeService(countryRepo, stateRepo)
 	uomCategoryService := service.NewUOMCategoryService(uomCategoryRepo)
 	uomUnitService := service.NewUOMUnitService(uomUnitRepo)
 	paymentTermService := service.NewPaymentTermService(paymentTermRepo)
@@ -76,10 +79,10 @@ func (m *CommonModule) Init(ctx context.Context, deps registry.Dependencies) err
 	utmSourceService := service.NewUTMSourceService(utmSourceRepo)
 
 	// Create handlers
-	m.attachmentHandler = handler.NewAttachmentHandler(attachmentService)
 	m.currencyHandler = handler.NewCurrencyHandler(currencyService)
 	m.countryHandler = handler.NewCountryHandler(countryService)
 	m.stateHandler = handler.NewStateHandler(stateService)
+	m.isoCodeHandler = handler.NewISOCodeHandler(isoCodeService)
 	m.uomCategoryHandler = handler.NewUOMCategoryHandler(uomCategoryService)
 	m.uomUnitHandler = handler.NewUOMUnitHandler(uomUnitService)
 	m.paymentTermHandler = handler.NewPaymentTermHandler(paymentTermService)
@@ -94,6 +97,11 @@ func (m *CommonModule) Init(ctx context.Context, deps registry.Dependencies) err
 	return nil
 }
 
+// SetStorage enables attachments, whose files are kept in the storage
+func (m *CommonModule) SetStorage(store storage.Storage) {
+	m.attachmentHandler = handler.NewAttachmentHandler(service.NewAttachmentService(m.attachmentRepo, store))
+}
+
 // RegisterRoutes registers common module routes
 func (m *CommonModule) RegisterRoutes(router interface{}) {
 	if router == nil {
@@ -113,6 +121,9 @@ func (m *CommonModule) RegisterRoutes(router interface{}) {
 		if m.stateHandler != nil {
 			m.stateHandler.RegisterRoutes(r)
 		}
+		if m.isoCodeHandler != nil {
+			m.isoCodeHandler.RegisterRoutes(r)
+		}
 		if m.uomCategoryHandler != nil {
 			m.uomCategoryHandler.RegisterRoutes(r)
 		}
@@ -143,6 +154,11 @@ func (m *CommonModule) RegisterRoutes(router interface{}) {
 	}
 }
 
+// RegisterEventHandlers registers event handlers for the common module
+func (m *CommonModule) RegisterEventHandlers(bus interface{}) {
+	// Reference data is only changed through its own endpoints; nothing to consume
+}
+
 // Health checks the health of the common module
 func (m *CommonModule) Health() error {
 	return nil
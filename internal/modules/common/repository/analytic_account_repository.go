@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+)
+
+const analyticAccountColumns = `id, organization_id, company_id, name, COALESCE(code, ''), partner_id, COALESCE(active, true),
+	created_at, updated_at, created_by, updated_by, deleted_at`
+
+// AnalyticAccountRepository handles analytic account data operations
+type AnalyticAccountRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticAccountRepository(db *sql.DB) *AnalyticAccountRepository {
+	return &AnalyticAccountRepository{db: db}
+}
+
+func scanAnalyticAccount(row interface{ Scan(...interface{}) error }) (*types.AnalyticAccount, error) {
+	var account types.AnalyticAccount
+	err := row.Scan(
+		&account.ID, &account.OrganizationID, &account.CompanyID, &account.Name, &account.Code, &account.PartnerID, &account.Active,
+		&account.CreatedAt, &account.UpdatedAt, &account.CreatedBy, &account.UpdatedBy, &account.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *AnalyticAccountRepository) Create(ctx context.Context, account types.AnalyticAccount) (*types.AnalyticAccount, error) {
+	if account.ID == uuid.Nil {
+		account.ID = uuid.New()
+	}
+
+	if account.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+
+	if account.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO analytic_accounts (
+			id, organization_id, company_id, name, code, partner_id, active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $8
+		) RETURNING ` + analyticAccountColumns
+
+	return scanAnalyticAccount(r.db.QueryRowContext(ctx, query,
+		account.ID, account.OrganizationID, account.CompanyID, account.Name, account.Code, account.PartnerID, account.Active, time.Now(),
+	))
+}
+
+func (r *AnalyticAccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.AnalyticAccount, error) {
+	query := `SELECT ` + analyticAccountColumns + ` FROM analytic_accounts WHERE id = $1 AND deleted_at IS NULL`
+
+	account, err := scanAnalyticAccount(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (r *AnalyticAccountRepository) List(ctx context.Context, filter types.AnalyticAccountFilter) ([]types.AnalyticAccount, error) {
+	query := `SELECT ` + analyticAccountColumns + ` FROM analytic_accounts WHERE deleted_at IS NULL`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.CompanyID != nil {
+		query += fmt.Sprintf(" AND company_id = $%d", paramIndex)
+		params = append(params, *filter.CompanyID)
+		paramIndex++
+	}
+
+	if filter.Active != nil {
+		query += fmt.Sprintf(" AND COALESCE(active, true) = $%d", paramIndex)
+		params = append(params, *filter.Active)
+		paramIndex++
+	}
+
+	if filter.Code != nil {
+		query += fmt.Sprintf(" AND code = $%d", paramIndex)
+		params = append(params, *filter.Code)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY code NULLS LAST, name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []types.AnalyticAccount
+	for rows.Next() {
+		account, err := scanAnalyticAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *AnalyticAccountRepository) Update(ctx context.Context, id uuid.UUID, update types.AnalyticAccountUpdateRequest) (*types.AnalyticAccount, error) {
+	if update.CompanyID == nil && update.Name == nil && update.Code == nil &&
+		update.PartnerID == nil && update.Active == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := "UPDATE analytic_accounts SET "
+	params := []interface{}{}
+	paramIndex := 1
+
+	if update.CompanyID != nil {
+		query += fmt.Sprintf("company_id = $%d, ", paramIndex)
+		params = append(params, *update.CompanyID)
+		paramIndex++
+	}
+
+	if update.Name != nil {
+		query += fmt.Sprintf("name = $%d, ", paramIndex)
+		params = append(params, *update.Name)
+		paramIndex++
+	}
+
+	if update.Code != nil {
+		query += fmt.Sprintf("code = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.Code)
+		paramIndex++
+	}
+
+	if update.PartnerID != nil {
+		query += fmt.Sprintf("partner_id = $%d, ", paramIndex)
+		params = append(params, *update.PartnerID)
+		paramIndex++
+	}
+
+	if update.Active != nil {
+		query += fmt.Sprintf("active = $%d, ", paramIndex)
+		params = append(params, *update.Active)
+		paramIndex++
+	}
+
+	query += fmt.Sprintf("updated_at = $%d WHERE id = $%d AND deleted_at IS NULL RETURNING %s", paramIndex, paramIndex+1, analyticAccountColumns)
+	params = append(params, time.Now(), id)
+
+	return scanAnalyticAccount(r.db.QueryRowContext(ctx, query, params...))
+}
+
+func (r *AnalyticAccountRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE analytic_accounts SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
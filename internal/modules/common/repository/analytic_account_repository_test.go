@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestAnalyticAccountUpdateClearsEmptyCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	code := ""
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE analytic_accounts SET code = NULLIF($1, ''), updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`)).
+		WithArgs("", sqlmock.AnyArg(), id).
+		WillReturnError(context.Canceled)
+
+	_, err = NewAnalyticAccountRepository(db).Update(context.Background(), id, types.AnalyticAccountUpdateRequest{Code: &code})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = NewAnalyticAccountRepository(db).Update(context.Background(), id, types.AnalyticAccountUpdateRequest{})
+	assert.EqualError(t, err, "no fields to update")
+}
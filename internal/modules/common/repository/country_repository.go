@@ -8,9 +8,14 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countryColumns = `id, code, COALESCE(alpha3, ''), COALESCE(numeric_code, ''), name,
+	COALESCE(phone_code, ''), currency_id, COALESCE(address_format, ''), created_at`
+
 // CountryRepository handles country data operations
 type CountryRepository struct {
 	db *sql.DB
@@ -20,6 +25,41 @@ func NewCountryRepository(db *sql.DB) *CountryRepository {
 	return &CountryRepository{db: db}
 }
 
+// DB returns the database the repository queries
+func (r *CountryRepository) DB() *sql.DB {
+	return r.db
+}
+
+func scanCountry(row interface{ Scan(...interface{}) error }) (*types.Country, error) {
+	var country types.Country
+	if err := row.Scan(
+		&country.ID, &country.Code, &country.Alpha3, &country.NumericCode, &country.Name,
+		&country.PhoneCode, &country.CurrencyID, &country.AddressFormat, &country.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &country, nil
+}
+
+func (r *CountryRepository) queryCountries(ctx context.Context, query string, params ...interface{}) ([]types.Country, error) {
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var countries []types.Country
+	for rows.Next() {
+		country, err := scanCountry(rows)
+		if err != nil {
+			return nil, err
+		}
+		countries = append(countries, *country)
+	}
+
+	return countries, rows.Err()
+}
+
 func (r *CountryRepository) Create(ctx context.Context, country types.Country) (*types.Country, error) {
 	if country.ID == uuid.Nil {
 		country.ID = uuid.New()
@@ -35,40 +75,21 @@ func (r *CountryRepository) Create(ctx context.Context, country types.Country) (
 
 	query := `
 		INSERT INTO countries (
-			id, code, name, phone_code, currency_id, address_format, created_at
+			id, code, alpha3, numeric_code, name, phone_code, currency_id, address_format, created_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7
-		) RETURNING id, code, name, phone_code, currency_id, address_format, created_at
-	`
-
-	now := time.Now()
-
-	var created types.Country
-	err := r.db.QueryRowContext(ctx, query,
-		country.ID, country.Code, country.Name, country.PhoneCode, country.CurrencyID, country.AddressFormat, now,
-	).Scan(
-		&created.ID, &created.Code, &created.Name, &created.PhoneCode, &created.CurrencyID, &created.AddressFormat, &created.CreatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
+			$1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9
+		) RETURNING ` + countryColumns
 
-	return &created, nil
+	return scanCountry(r.db.QueryRowContext(ctx, query,
+		country.ID, country.Code, country.Alpha3, country.NumericCode, country.Name,
+		country.PhoneCode, country.CurrencyID, country.AddressFormat, time.Now(),
+	))
 }
 
 func (r *CountryRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.Country, error) {
-	query := `
-		SELECT id, code, name, phone_code, currency_id, address_format, created_at
-		FROM countries
-		WHERE id = $1
-	`
-
-	var country types.Country
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&country.ID, &country.Code, &country.Name, &country.PhoneCode, &country.CurrencyID, &country.AddressFormat, &country.CreatedAt,
-	)
+	query := `SELECT ` + countryColumns + ` FROM countries WHERE id = $1`
 
+	country, err := scanCountry(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -76,21 +97,13 @@ func (r *CountryRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.C
 		return nil, err
 	}
 
-	return &country, nil
+	return country, nil
 }
 
 func (r *CountryRepository) GetByCode(ctx context.Context, code string) (*types.Country, error) {
-	query := `
-		SELECT id, code, name, phone_code, currency_id, address_format, created_at
-		FROM countries
-		WHERE code = $1
-	`
-
-	var country types.Country
-	err := r.db.QueryRowContext(ctx, query, code).Scan(
-		&country.ID, &country.Code, &country.Name, &country.PhoneCode, &country.CurrencyID, &country.AddressFormat, &country.CreatedAt,
-	)
+	query := `SELECT ` + countryColumns + ` FROM countries WHERE code = $1`
 
+	country, err := scanCountry(r.db.QueryRowContext(ctx, query, code))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -98,16 +111,32 @@ func (r *CountryRepository) GetByCode(ctx context.Context, code string) (*types.
 		return nil, err
 	}
 
-	return &country, nil
+	return country, nil
 }
 
-func (r *CountryRepository) List(ctx context.Context, filter types.CountryFilter) ([]types.Country, error) {
+// Find returns the countries matching any code or name of the lookup
+func (r *CountryRepository) Find(ctx context.Context, lookup types.CountryLookup) ([]types.Country, error) {
 	query := `
-		SELECT id, code, name, phone_code, currency_id, address_format, created_at
+		SELECT ` + countryColumns + `
 		FROM countries
-		WHERE (1=1)
+		WHERE code = ANY($1) OR alpha3 = ANY($2) OR numeric_code = ANY($3) OR lower(name) = ANY($4)
+		ORDER BY code
 	`
 
+	return r.queryCountries(ctx, query,
+		pq.Array(lookup.Codes), pq.Array(lookup.Alpha3), pq.Array(lookup.NumericCodes), pq.Array(lookup.Names),
+	)
+}
+
+// ListByIDs returns the countries with the IDs; unknown IDs are left out
+func (r *CountryRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]types.Country, error) {
+	query := `SELECT ` + countryColumns + ` FROM countries WHERE id = ANY($1) ORDER BY code`
+	return r.queryCountries(ctx, query, pq.Array(ids))
+}
+
+func (r *CountryRepository) List(ctx context.Context, filter types.CountryFilter) ([]types.Country, error) {
+	query := `SELECT ` + countryColumns + ` FROM countries WHERE (1=1)`
+
 	params := []interface{}{}
 	paramIndex := 1
 
@@ -118,8 +147,8 @@ func (r *CountryRepository) List(ctx context.Context, filter types.CountryFilter
 	}
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIndex)
-		params = append(params, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, filter.MatchMode))
 		paramIndex++
 	}
 
@@ -136,29 +165,12 @@ func (r *CountryRepository) List(ctx context.Context, filter types.CountryFilter
 		params = append(params, filter.Offset)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, params...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var countries []types.Country
-	for rows.Next() {
-		var country types.Country
-		err := rows.Scan(
-			&country.ID, &country.Code, &country.Name, &country.PhoneCode, &country.CurrencyID, &country.AddressFormat, &country.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		countries = append(countries, country)
-	}
-
-	return countries, nil
+	return r.queryCountries(ctx, query, params...)
 }
 
 func (r *CountryRepository) Update(ctx context.Context, id uuid.UUID, update types.CountryUpdateRequest) (*types.Country, error) {
-	if update.Code == nil && update.Name == nil && update.PhoneCode == nil && update.CurrencyID == nil && update.AddressFormat == nil {
+	if update.Code == nil && update.Alpha3 == nil && update.NumericCode == nil && update.Name == nil &&
+		update.PhoneCode == nil && update.CurrencyID == nil && update.AddressFormat == nil {
 		return nil, errors.New("no fields to update")
 	}
 
@@ -172,6 +184,18 @@ func (r *CountryRepository) Update(ctx context.Context, id uuid.UUID, update typ
 		paramIndex++
 	}
 
+	if update.Alpha3 != nil {
+		query += fmt.Sprintf("alpha3 = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.Alpha3)
+		paramIndex++
+	}
+
+	if update.NumericCode != nil {
+		query += fmt.Sprintf("numeric_code = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.NumericCode)
+		paramIndex++
+	}
+
 	if update.Name != nil {
 		query += fmt.Sprintf("name = $%d, ", paramIndex)
 		params = append(params, *update.Name)
@@ -198,19 +222,10 @@ func (r *CountryRepository) Update(ctx context.Context, id uuid.UUID, update typ
 
 	// Remove trailing comma and space
 	query = query[:len(query)-2]
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, code, name, phone_code, currency_id, address_format, created_at", paramIndex)
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING %s", paramIndex, countryColumns)
 	params = append(params, id)
 
-	var updated types.Country
-	err := r.db.QueryRowContext(ctx, query, params...).Scan(
-		&updated.ID, &updated.Code, &updated.Name, &updated.PhoneCode, &updated.CurrencyID, &updated.AddressFormat, &updated.CreatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &updated, nil
+	return scanCountry(r.db.QueryRowContext(ctx, query, params...))
 }
 
 func (r *CountryRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 )
 
@@ -152,8 +153,8 @@ func (r *CurrencyRepository) List(ctx context.Context, filter types.CurrencyFilt
 	}
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIndex)
-		params = append(params, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, filter.MatchMode))
 		paramIndex++
 	}
 
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const fiscalPositionColumns = `id, organization_id, company_id, name, COALESCE(auto_apply, false), COALESCE(vat_required, false),
+	country_id, state_ids, zip_from, zip_to, COALESCE(note, ''), COALESCE(active, true),
+	created_at, updated_at, created_by, updated_by, deleted_at`
+
+// FiscalPositionRepository handles fiscal position data operations
+type FiscalPositionRepository struct {
+	db *sql.DB
+}
+
+func NewFiscalPositionRepository(db *sql.DB) *FiscalPositionRepository {
+	return &FiscalPositionRepository{db: db}
+}
+
+func scanFiscalPosition(row interface{ Scan(...interface{}) error }) (*types.FiscalPosition, error) {
+	var position types.FiscalPosition
+	err := row.Scan(
+		&position.ID, &position.OrganizationID, &position.CompanyID, &position.Name, &position.AutoApply, &position.VATRequired,
+		&position.CountryID, pq.Array(&position.StateIDs), &position.ZipFrom, &position.ZipTo, &position.Note, &position.Active,
+		&position.CreatedAt, &position.UpdatedAt, &position.CreatedBy, &position.UpdatedBy, &position.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if position.StateIDs == nil {
+		position.StateIDs = []uuid.UUID{}
+	}
+	return &position, nil
+}
+
+func (r *FiscalPositionRepository) Create(ctx context.Context, position types.FiscalPosition) (*types.FiscalPosition, error) {
+	if position.ID == uuid.Nil {
+		position.ID = uuid.New()
+	}
+
+	if position.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+
+	if position.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO fiscal_positions (
+			id, organization_id, company_id, name, auto_apply, vat_required,
+			country_id, state_ids, zip_from, zip_to, note, active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13
+		) RETURNING ` + fiscalPositionColumns
+
+	return scanFiscalPosition(r.db.QueryRowContext(ctx, query,
+		position.ID, position.OrganizationID, position.CompanyID, position.Name, position.AutoApply, position.VATRequired,
+		position.CountryID, pq.Array(position.StateIDs), position.ZipFrom, position.ZipTo, position.Note, position.Active, time.Now(),
+	))
+}
+
+func (r *FiscalPositionRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.FiscalPosition, error) {
+	query := `SELECT ` + fiscalPositionColumns + ` FROM fiscal_positions WHERE id = $1 AND deleted_at IS NULL`
+
+	position, err := scanFiscalPosition(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return position, nil
+}
+
+func (r *FiscalPositionRepository) List(ctx context.Context, filter types.FiscalPositionFilter) ([]types.FiscalPosition, error) {
+	query := `SELECT ` + fiscalPositionColumns + ` FROM fiscal_positions WHERE deleted_at IS NULL`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.CompanyID != nil {
+		query += fmt.Sprintf(" AND company_id = $%d", paramIndex)
+		params = append(params, *filter.CompanyID)
+		paramIndex++
+	}
+
+	if filter.CountryID != nil {
+		query += fmt.Sprintf(" AND country_id = $%d", paramIndex)
+		params = append(params, *filter.CountryID)
+		paramIndex++
+	}
+
+	if filter.Active != nil {
+		query += fmt.Sprintf(" AND COALESCE(active, true) = $%d", paramIndex)
+		params = append(params, *filter.Active)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []types.FiscalPosition
+	for rows.Next() {
+		position, err := scanFiscalPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, *position)
+	}
+
+	return positions, rows.Err()
+}
+
+func (r *FiscalPositionRepository) Update(ctx context.Context, id uuid.UUID, update types.FiscalPositionUpdateRequest) (*types.FiscalPosition, error) {
+	query := "UPDATE fiscal_positions SET "
+	params := []interface{}{}
+	paramIndex := 1
+
+	set := func(column string, value interface{}) {
+		query += fmt.Sprintf("%s = $%d, ", column, paramIndex)
+		params = append(params, value)
+		paramIndex++
+	}
+
+	if update.CompanyID != nil {
+		set("company_id", *update.CompanyID)
+	}
+	if update.Name != nil {
+		set("name", *update.Name)
+	}
+	if update.AutoApply != nil {
+		set("auto_apply", *update.AutoApply)
+	}
+	if update.VATRequired != nil {
+		set("vat_required", *update.VATRequired)
+	}
+	if update.CountryID != nil {
+		set("country_id", *update.CountryID)
+	}
+	if update.StateIDs != nil {
+		set("state_ids", pq.Array(*update.StateIDs))
+	}
+	if update.ZipFrom != nil {
+		set("zip_from", *update.ZipFrom)
+	}
+	if update.ZipTo != nil {
+		set("zip_to", *update.ZipTo)
+	}
+	if update.Note != nil {
+		set("note", *update.Note)
+	}
+	if update.Active != nil {
+		set("active", *update.Active)
+	}
+
+	if len(params) == 0 {
+		return nil, errors.New("no fields to update")
+	}
+
+	query += fmt.Sprintf("updated_at = $%d WHERE id = $%d AND deleted_at IS NULL RETURNING %s", paramIndex, paramIndex+1, fiscalPositionColumns)
+	params = append(params, time.Now(), id)
+
+	return scanFiscalPosition(r.db.QueryRowContext(ctx, query, params...))
+}
+
+func (r *FiscalPositionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE fiscal_positions SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+)
+
+const industryColumns = `id, name, COALESCE(code, ''), COALESCE(full_name, ''), created_at`
+
+// IndustryRepository handles industry data operations
+type IndustryRepository struct {
+	db *sql.DB
+}
+
+func NewIndustryRepository(db *sql.DB) *IndustryRepository {
+	return &IndustryRepository{db: db}
+}
+
+func scanIndustry(row interface{ Scan(...interface{}) error }) (*types.Industry, error) {
+	var industry types.Industry
+	if err := row.Scan(&industry.ID, &industry.Name, &industry.Code, &industry.FullName, &industry.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &industry, nil
+}
+
+func (r *IndustryRepository) Create(ctx context.Context, industry types.Industry) (*types.Industry, error) {
+	if industry.ID == uuid.Nil {
+		industry.ID = uuid.New()
+	}
+
+	if industry.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO industries (
+			id, name, code, full_name, created_at
+		) VALUES (
+			$1, $2, NULLIF($3, ''), NULLIF($4, ''), $5
+		) RETURNING ` + industryColumns
+
+	return scanIndustry(r.db.QueryRowContext(ctx, query,
+		industry.ID, industry.Name, industry.Code, industry.FullName, time.Now(),
+	))
+}
+
+func (r *IndustryRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.Industry, error) {
+	query := `SELECT ` + industryColumns + ` FROM industries WHERE id = $1`
+
+	industry, err := scanIndustry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return industry, nil
+}
+
+func (r *IndustryRepository) List(ctx context.Context, filter types.IndustryFilter) ([]types.Industry, error) {
+	query := `SELECT ` + industryColumns + ` FROM industries WHERE (1=1)`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.Code != nil {
+		query += fmt.Sprintf(" AND code = $%d", paramIndex)
+		params = append(params, *filter.Code)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND (" + database.ILike("name", paramIndex) + " OR " + database.ILike("full_name", paramIndex) + ")"
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var industries []types.Industry
+	for rows.Next() {
+		industry, err := scanIndustry(rows)
+		if err != nil {
+			return nil, err
+		}
+		industries = append(industries, *industry)
+	}
+
+	return industries, rows.Err()
+}
+
+func (r *IndustryRepository) Update(ctx context.Context, id uuid.UUID, update types.IndustryUpdateRequest) (*types.Industry, error) {
+	if update.Name == nil && update.Code == nil && update.FullName == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := "UPDATE industries SET "
+	params := []interface{}{}
+	paramIndex := 1
+
+	if update.Name != nil {
+		query += fmt.Sprintf("name = $%d, ", paramIndex)
+		params = append(params, *update.Name)
+		paramIndex++
+	}
+
+	if update.Code != nil {
+		query += fmt.Sprintf("code = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.Code)
+		paramIndex++
+	}
+
+	if update.FullName != nil {
+		query += fmt.Sprintf("full_name = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.FullName)
+		paramIndex++
+	}
+
+	// Remove trailing comma and space
+	query = query[:len(query)-2]
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING %s", paramIndex, industryColumns)
+	params = append(params, id)
+
+	return scanIndustry(r.db.QueryRowContext(ctx, query, params...))
+}
+
+func (r *IndustryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "DELETE FROM industries WHERE id = $1"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+)
+
+const paymentTermColumns = `id, organization_id, company_id, name, COALESCE(note, ''), COALESCE(active, true),
+	created_at, updated_at, created_by, updated_by, deleted_at`
+
+// PaymentTermRepository handles payment term data operations
+type PaymentTermRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentTermRepository(db *sql.DB) *PaymentTermRepository {
+	return &PaymentTermRepository{db: db}
+}
+
+func scanPaymentTerm(row interface{ Scan(...interface{}) error }) (*types.PaymentTerm, error) {
+	var term types.PaymentTerm
+	err := row.Scan(
+		&term.ID, &term.OrganizationID, &term.CompanyID, &term.Name, &term.Note, &term.Active,
+		&term.CreatedAt, &term.UpdatedAt, &term.CreatedBy, &term.UpdatedBy, &term.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+func (r *PaymentTermRepository) Create(ctx context.Context, term types.PaymentTerm) (*types.PaymentTerm, error) {
+	if term.ID == uuid.Nil {
+		term.ID = uuid.New()
+	}
+
+	if term.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+
+	if term.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO payment_terms (
+			id, organization_id, company_id, name, note, active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $7
+		) RETURNING ` + paymentTermColumns
+
+	return scanPaymentTerm(r.db.QueryRowContext(ctx, query,
+		term.ID, term.OrganizationID, term.CompanyID, term.Name, term.Note, term.Active, time.Now(),
+	))
+}
+
+func (r *PaymentTermRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.PaymentTerm, error) {
+	query := `SELECT ` + paymentTermColumns + ` FROM payment_terms WHERE id = $1 AND deleted_at IS NULL`
+
+	term, err := scanPaymentTerm(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return term, nil
+}
+
+func (r *PaymentTermRepository) List(ctx context.Context, filter types.PaymentTermFilter) ([]types.PaymentTerm, error) {
+	query := `SELECT ` + paymentTermColumns + ` FROM payment_terms WHERE deleted_at IS NULL`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.CompanyID != nil {
+		query += fmt.Sprintf(" AND company_id = $%d", paramIndex)
+		params = append(params, *filter.CompanyID)
+		paramIndex++
+	}
+
+	if filter.Active != nil {
+		query += fmt.Sprintf(" AND COALESCE(active, true) = $%d", paramIndex)
+		params = append(params, *filter.Active)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terms []types.PaymentTerm
+	for rows.Next() {
+		term, err := scanPaymentTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, *term)
+	}
+
+	return terms, rows.Err()
+}
+
+func (r *PaymentTermRepository) Update(ctx context.Context, id uuid.UUID, update types.PaymentTermUpdateRequest) (*types.PaymentTerm, error) {
+	if update.CompanyID == nil && update.Name == nil && update.Note == nil && update.Active == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := "UPDATE payment_terms SET "
+	params := []interface{}{}
+	paramIndex := 1
+
+	if update.CompanyID != nil {
+		query += fmt.Sprintf("company_id = $%d, ", paramIndex)
+		params = append(params, *update.CompanyID)
+		paramIndex++
+	}
+
+	if update.Name != nil {
+		query += fmt.Sprintf("name = $%d, ", paramIndex)
+		params = append(params, *update.Name)
+		paramIndex++
+	}
+
+	if update.Note != nil {
+		query += fmt.Sprintf("note = $%d, ", paramIndex)
+		params = append(params, *update.Note)
+		paramIndex++
+	}
+
+	if update.Active != nil {
+		query += fmt.Sprintf("active = $%d, ", paramIndex)
+		params = append(params, *update.Active)
+		paramIndex++
+	}
+
+	query += fmt.Sprintf("updated_at = $%d WHERE id = $%d AND deleted_at IS NULL RETURNING %s", paramIndex, paramIndex+1, paymentTermColumns)
+	params = append(params, time.Now(), id)
+
+	return scanPaymentTerm(r.db.QueryRowContext(ctx, query, params...))
+}
+
+func (r *PaymentTermRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE payment_terms SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
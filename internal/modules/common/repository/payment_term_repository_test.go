@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestPaymentTermListSkipsDeletedAndFiltersOrganization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID := uuid.New()
+	name := "30"
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM payment_terms WHERE deleted_at IS NULL AND organization_id = $1 AND name ILIKE $2 ESCAPE '\' ORDER BY name LIMIT $3`)).
+		WithArgs(orgID, "%30%", 10).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "organization_id", "company_id", "name", "note", "active",
+			"created_at", "updated_at", "created_by", "updated_by", "deleted_at",
+		}).AddRow(uuid.New(), orgID, nil, "30 days", "", true, now, now, nil, nil, nil))
+
+	terms, err := NewPaymentTermRepository(db).List(context.Background(), types.PaymentTermFilter{
+		OrganizationID: &orgID,
+		Name:           &name,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	require.Len(t, terms, 1)
+	assert.Equal(t, "30 days", terms[0].Name)
+	assert.True(t, terms[0].Active)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentTermDeleteIsSoft(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE payment_terms SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, NewPaymentTermRepository(db).Delete(context.Background(), id))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -8,9 +8,13 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const stateColumns = `id, country_id, name, code, COALESCE(iso_code, ''), COALESCE(subdivision_type, ''), parent_id, created_at`
+
 // StateRepository handles state data operations
 type StateRepository struct {
 	db *sql.DB
@@ -20,6 +24,40 @@ func NewStateRepository(db *sql.DB) *StateRepository {
 	return &StateRepository{db: db}
 }
 
+// DB returns the database the repository queries
+func (r *StateRepository) DB() *sql.DB {
+	return r.db
+}
+
+func scanState(row interface{ Scan(...interface{}) error }) (*types.State, error) {
+	var state types.State
+	if err := row.Scan(
+		&state.ID, &state.CountryID, &state.Name, &state.Code, &state.ISOCode, &state.Type, &state.ParentID, &state.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *StateRepository) queryStates(ctx context.Context, query string, params ...interface{}) ([]types.State, error) {
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []types.State
+	for rows.Next() {
+		state, err := scanState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *state)
+	}
+
+	return states, rows.Err()
+}
+
 func (r *StateRepository) Create(ctx context.Context, state types.State) (*types.State, error) {
 	if state.ID == uuid.Nil {
 		state.ID = uuid.New()
@@ -39,40 +77,20 @@ func (r *StateRepository) Create(ctx context.Context, state types.State) (*types
 
 	query := `
 		INSERT INTO states (
-			id, country_id, name, code, created_at
+			id, country_id, name, code, iso_code, subdivision_type, parent_id, created_at
 		) VALUES (
-			$1, $2, $3, $4, $5
-		) RETURNING id, country_id, name, code, created_at
-	`
-
-	now := time.Now()
+			$1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8
+		) RETURNING ` + stateColumns
 
-	var created types.State
-	err := r.db.QueryRowContext(ctx, query,
-		state.ID, state.CountryID, state.Name, state.Code, now,
-	).Scan(
-		&created.ID, &created.CountryID, &created.Name, &created.Code, &created.CreatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &created, nil
+	return scanState(r.db.QueryRowContext(ctx, query,
+		state.ID, state.CountryID, state.Name, state.Code, state.ISOCode, state.Type, state.ParentID, time.Now(),
+	))
 }
 
 func (r *StateRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.State, error) {
-	query := `
-		SELECT id, country_id, name, code, created_at
-		FROM states
-		WHERE id = $1
-	`
-
-	var state types.State
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&state.ID, &state.CountryID, &state.Name, &state.Code, &state.CreatedAt,
-	)
+	query := `SELECT ` + stateColumns + ` FROM states WHERE id = $1`
 
+	state, err := scanState(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -80,44 +98,35 @@ func (r *StateRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.Sta
 		return nil, err
 	}
 
-	return &state, nil
+	return state, nil
 }
 
 func (r *StateRepository) ListByCountry(ctx context.Context, countryID uuid.UUID) ([]types.State, error) {
+	query := `SELECT ` + stateColumns + ` FROM states WHERE country_id = $1 ORDER BY name`
+	return r.queryStates(ctx, query, countryID)
+}
+
+// Find returns the states matching an ISO code of the lookup, or one of its
+// names within one of its countries
+func (r *StateRepository) Find(ctx context.Context, lookup types.StateLookup) ([]types.State, error) {
 	query := `
-		SELECT id, country_id, name, code, created_at
+		SELECT ` + stateColumns + `
 		FROM states
-		WHERE country_id = $1
-		ORDER BY name
+		WHERE iso_code = ANY($1) OR (country_id = ANY($3) AND lower(name) = ANY($2))
+		ORDER BY iso_code, id
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, countryID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var states []types.State
-	for rows.Next() {
-		var state types.State
-		err := rows.Scan(
-			&state.ID, &state.CountryID, &state.Name, &state.Code, &state.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		states = append(states, state)
-	}
+	return r.queryStates(ctx, query, pq.Array(lookup.ISOCodes), pq.Array(lookup.Names), pq.Array(lookup.CountryIDs))
+}
 
-	return states, nil
+// ListByIDs returns the states with the IDs; unknown IDs are left out
+func (r *StateRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]types.State, error) {
+	query := `SELECT ` + stateColumns + ` FROM states WHERE id = ANY($1) ORDER BY iso_code`
+	return r.queryStates(ctx, query, pq.Array(ids))
 }
 
 func (r *StateRepository) List(ctx context.Context, filter types.StateFilter) ([]types.State, error) {
-	query := `
-		SELECT id, country_id, name, code, created_at
-		FROM states
-		WHERE (1=1)
-	`
+	query := `SELECT ` + stateColumns + ` FROM states WHERE (1=1)`
 
 	params := []interface{}{}
 	paramIndex := 1
@@ -134,9 +143,29 @@ func (r *StateRepository) List(ctx context.Context, filter types.StateFilter) ([
 		paramIndex++
 	}
 
+	if filter.ISOCode != nil {
+		query += fmt.Sprintf(" AND iso_code = upper($%d)", paramIndex)
+		params = append(params, *filter.ISOCode)
+		paramIndex++
+	}
+
+	if filter.Type != nil {
+		query += " AND " + database.ILike("subdivision_type", paramIndex)
+		params = append(params, database.LikePattern(*filter.Type, database.MatchModeExact))
+		paramIndex++
+	}
+
+	if filter.ParentID != nil {
+		query += fmt.Sprintf(" AND parent_id = $%d", paramIndex)
+		params = append(params, *filter.ParentID)
+		paramIndex++
+	} else if filter.TopLevel {
+		query += " AND parent_id IS NULL"
+	}
+
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIndex)
-		params = append(params, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, filter.MatchMode))
 		paramIndex++
 	}
 
@@ -153,29 +182,12 @@ func (r *StateRepository) List(ctx context.Context, filter types.StateFilter) ([
 		params = append(params, filter.Offset)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, params...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var states []types.State
-	for rows.Next() {
-		var state types.State
-		err := rows.Scan(
-			&state.ID, &state.CountryID, &state.Name, &state.Code, &state.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		states = append(states, state)
-	}
-
-	return states, nil
+	return r.queryStates(ctx, query, params...)
 }
 
 func (r *StateRepository) Update(ctx context.Context, id uuid.UUID, update types.StateUpdateRequest) (*types.State, error) {
-	if update.CountryID == nil && update.Name == nil && update.Code == nil {
+	if update.CountryID == nil && update.Name == nil && update.Code == nil &&
+		update.ISOCode == nil && update.Type == nil && update.ParentID == nil {
 		return nil, errors.New("no fields to update")
 	}
 
@@ -201,21 +213,30 @@ func (r *StateRepository) Update(ctx context.Context, id uuid.UUID, update types
 		paramIndex++
 	}
 
-	// Remove trailing comma and space
-	query = query[:len(query)-2]
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, country_id, name, code, created_at", paramIndex)
-	params = append(params, id)
+	if update.ISOCode != nil {
+		query += fmt.Sprintf("iso_code = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.ISOCode)
+		paramIndex++
+	}
 
-	var updated types.State
-	err := r.db.QueryRowContext(ctx, query, params...).Scan(
-		&updated.ID, &updated.CountryID, &updated.Name, &updated.Code, &updated.CreatedAt,
-	)
+	if update.Type != nil {
+		query += fmt.Sprintf("subdivision_type = NULLIF($%d, ''), ", paramIndex)
+		params = append(params, *update.Type)
+		paramIndex++
+	}
 
-	if err != nil {
-		return nil, err
+	if update.ParentID != nil {
+		query += fmt.Sprintf("parent_id = $%d, ", paramIndex)
+		params = append(params, *update.ParentID)
+		paramIndex++
 	}
 
-	return &updated, nil
+	// Remove trailing comma and space
+	query = query[:len(query)-2]
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING %s", paramIndex, stateColumns)
+	params = append(params, id)
+
+	return scanState(r.db.QueryRowContext(ctx, query, params...))
 }
 
 func (r *StateRepository) Delete(ctx context.Context, id uuid.UUID) error {
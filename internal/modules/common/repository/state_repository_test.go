@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestStateListFiltersSubdivisionType(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	countryID := uuid.New()
+	subdivisionType := "province"
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT `+stateColumns+` FROM states WHERE (1=1) AND country_id = $1 AND subdivision_type ILIKE $2 ESCAPE '\' AND parent_id IS NULL ORDER BY name`)).
+		WithArgs(countryID, "province").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "country_id", "name", "code", "iso_code", "subdivision_type", "parent_id", "created_at"}))
+
+	states, err := NewStateRepository(db).List(context.Background(), types.StateFilter{
+		CountryID: &countryID,
+		Type:      &subdivisionType,
+		TopLevel:  true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, states)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
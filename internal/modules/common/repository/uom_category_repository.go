@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 )
 
@@ -20,6 +21,11 @@ func NewUOMCategoryRepository(db *sql.DB) *UOMCategoryRepository {
 	return &UOMCategoryRepository{db: db}
 }
 
+// DB returns the database the repository queries
+func (r *UOMCategoryRepository) DB() *sql.DB {
+	return r.db
+}
+
 func (r *UOMCategoryRepository) Create(ctx context.Context, category types.UOMCategory) (*types.UOMCategory, error) {
 	if category.ID == uuid.Nil {
 		category.ID = uuid.New()
@@ -86,8 +92,8 @@ func (r *UOMCategoryRepository) List(ctx context.Context, filter types.UOMCatego
 	paramIndex := 1
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIndex)
-		params = append(params, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, filter.MatchMode))
 		paramIndex++
 	}
 
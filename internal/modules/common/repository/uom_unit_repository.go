@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 )
 
@@ -20,6 +21,11 @@ func NewUOMUnitRepository(db *sql.DB) *UOMUnitRepository {
 	return &UOMUnitRepository{db: db}
 }
 
+// DB returns the database the repository queries
+func (r *UOMUnitRepository) DB() *sql.DB {
+	return r.db
+}
+
 func (r *UOMUnitRepository) Create(ctx context.Context, unit types.UOMUnit) (*types.UOMUnit, error) {
 	if unit.ID == uuid.Nil {
 		unit.ID = uuid.New()
@@ -151,8 +157,8 @@ func (r *UOMUnitRepository) List(ctx context.Context, filter types.UOMUnitFilter
 	}
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIndex)
-		params = append(params, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, filter.MatchMode))
 		paramIndex++
 	}
 
@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+)
+
+// The UTM campaign, medium and source tables share one shape, so their
+// repositories only differ in the table they read and write.
+const utmColumns = `id, organization_id, name, created_at`
+
+// UTMCampaignRepository handles UTM campaign data operations
+type UTMCampaignRepository struct {
+	db *sql.DB
+}
+
+func NewUTMCampaignRepository(db *sql.DB) *UTMCampaignRepository {
+	return &UTMCampaignRepository{db: db}
+}
+
+func scanUTMCampaign(row interface{ Scan(...interface{}) error }) (*types.UTMCampaign, error) {
+	var campaign types.UTMCampaign
+	if err := row.Scan(&campaign.ID, &campaign.OrganizationID, &campaign.Name, &campaign.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *UTMCampaignRepository) Create(ctx context.Context, campaign types.UTMCampaign) (*types.UTMCampaign, error) {
+	if campaign.ID == uuid.Nil {
+		campaign.ID = uuid.New()
+	}
+
+	if campaign.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if campaign.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO utm_campaigns (id, organization_id, name, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + utmColumns
+
+	return scanUTMCampaign(r.db.QueryRowContext(ctx, query, campaign.ID, campaign.OrganizationID, campaign.Name, time.Now()))
+}
+
+func (r *UTMCampaignRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMCampaign, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_campaigns WHERE id = $1`
+
+	campaign, err := scanUTMCampaign(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+func (r *UTMCampaignRepository) List(ctx context.Context, filter types.UTMCampaignFilter) ([]types.UTMCampaign, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_campaigns WHERE (1=1)`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []types.UTMCampaign
+	for rows.Next() {
+		campaign, err := scanUTMCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+func (r *UTMCampaignRepository) Update(ctx context.Context, id uuid.UUID, update types.UTMCampaignUpdateRequest) (*types.UTMCampaign, error) {
+	if update.Name == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := `UPDATE utm_campaigns SET name = $1 WHERE id = $2 RETURNING ` + utmColumns
+
+	return scanUTMCampaign(r.db.QueryRowContext(ctx, query, *update.Name, id))
+}
+
+func (r *UTMCampaignRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "DELETE FROM utm_campaigns WHERE id = $1"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// UTMMediumRepository handles UTM medium data operations
+type UTMMediumRepository struct {
+	db *sql.DB
+}
+
+func NewUTMMediumRepository(db *sql.DB) *UTMMediumRepository {
+	return &UTMMediumRepository{db: db}
+}
+
+func scanUTMMedium(row interface{ Scan(...interface{}) error }) (*types.UTMMedium, error) {
+	var medium types.UTMMedium
+	if err := row.Scan(&medium.ID, &medium.OrganizationID, &medium.Name, &medium.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &medium, nil
+}
+
+func (r *UTMMediumRepository) Create(ctx context.Context, medium types.UTMMedium) (*types.UTMMedium, error) {
+	if medium.ID == uuid.Nil {
+		medium.ID = uuid.New()
+	}
+
+	if medium.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if medium.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO utm_mediums (id, organization_id, name, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + utmColumns
+
+	return scanUTMMedium(r.db.QueryRowContext(ctx, query, medium.ID, medium.OrganizationID, medium.Name, time.Now()))
+}
+
+func (r *UTMMediumRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMMedium, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_mediums WHERE id = $1`
+
+	medium, err := scanUTMMedium(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return medium, nil
+}
+
+func (r *UTMMediumRepository) List(ctx context.Context, filter types.UTMMediumFilter) ([]types.UTMMedium, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_mediums WHERE (1=1)`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mediums []types.UTMMedium
+	for rows.Next() {
+		medium, err := scanUTMMedium(rows)
+		if err != nil {
+			return nil, err
+		}
+		mediums = append(mediums, *medium)
+	}
+
+	return mediums, rows.Err()
+}
+
+func (r *UTMMediumRepository) Update(ctx context.Context, id uuid.UUID, update types.UTMMediumUpdateRequest) (*types.UTMMedium, error) {
+	if update.Name == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := `UPDATE utm_mediums SET name = $1 WHERE id = $2 RETURNING ` + utmColumns
+
+	return scanUTMMedium(r.db.QueryRowContext(ctx, query, *update.Name, id))
+}
+
+func (r *UTMMediumRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "DELETE FROM utm_mediums WHERE id = $1"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// UTMSourceRepository handles UTM source data operations
+type UTMSourceRepository struct {
+	db *sql.DB
+}
+
+func NewUTMSourceRepository(db *sql.DB) *UTMSourceRepository {
+	return &UTMSourceRepository{db: db}
+}
+
+func scanUTMSource(row interface{ Scan(...interface{}) error }) (*types.UTMSource, error) {
+	var source types.UTMSource
+	if err := row.Scan(&source.ID, &source.OrganizationID, &source.Name, &source.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (r *UTMSourceRepository) Create(ctx context.Context, source types.UTMSource) (*types.UTMSource, error) {
+	if source.ID == uuid.Nil {
+		source.ID = uuid.New()
+	}
+
+	if source.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if source.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	query := `
+		INSERT INTO utm_sources (id, organization_id, name, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + utmColumns
+
+	return scanUTMSource(r.db.QueryRowContext(ctx, query, source.ID, source.OrganizationID, source.Name, time.Now()))
+}
+
+func (r *UTMSourceRepository) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMSource, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_sources WHERE id = $1`
+
+	source, err := scanUTMSource(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return source, nil
+}
+
+func (r *UTMSourceRepository) List(ctx context.Context, filter types.UTMSourceFilter) ([]types.UTMSource, error) {
+	query := `SELECT ` + utmColumns + ` FROM utm_sources WHERE (1=1)`
+
+	params := []interface{}{}
+	paramIndex := 1
+
+	if filter.OrganizationID != nil {
+		query += fmt.Sprintf(" AND organization_id = $%d", paramIndex)
+		params = append(params, *filter.OrganizationID)
+		paramIndex++
+	}
+
+	if filter.Name != nil {
+		query += " AND " + database.ILike("name", paramIndex)
+		params = append(params, database.LikePattern(*filter.Name, database.MatchModeContains))
+		paramIndex++
+	}
+
+	query += " ORDER BY name"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIndex)
+		params = append(params, filter.Limit)
+		paramIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", paramIndex)
+		params = append(params, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []types.UTMSource
+	for rows.Next() {
+		source, err := scanUTMSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *source)
+	}
+
+	return sources, rows.Err()
+}
+
+func (r *UTMSourceRepository) Update(ctx context.Context, id uuid.UUID, update types.UTMSourceUpdateRequest) (*types.UTMSource, error) {
+	if update.Name == nil {
+		return nil, errors.New("no fields to update")
+	}
+
+	query := `UPDATE utm_sources SET name = $1 WHERE id = $2 RETURNING ` + utmColumns
+
+	return scanUTMSource(r.db.QueryRowContext(ctx, query, *update.Name, id))
+}
+
+func (r *UTMSourceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := "DELETE FROM utm_sources WHERE id = $1"
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestUTMSourceListFiltersOrganizationAndName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID := uuid.New()
+	name := "news_letter"
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, organization_id, name, created_at FROM utm_sources WHERE (1=1) AND organization_id = $1 AND name ILIKE $2 ESCAPE '\' ORDER BY name LIMIT $3`)).
+		WithArgs(orgID, `%news\_letter%`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "name", "created_at"}))
+
+	sources, err := NewUTMSourceRepository(db).List(context.Background(), types.UTMSourceFilter{
+		OrganizationID: &orgID,
+		Name:           &name,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, sources)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
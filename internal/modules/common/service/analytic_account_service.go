@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// AnalyticAccountService handles business logic for analytic accounts
+type AnalyticAccountService struct {
+	repository *repository.AnalyticAccountRepository
+}
+
+func NewAnalyticAccountService(repository *repository.AnalyticAccountRepository) *AnalyticAccountService {
+	return &AnalyticAccountService{repository: repository}
+}
+
+func (s *AnalyticAccountService) Create(ctx context.Context, req types.AnalyticAccountCreateRequest) (*types.AnalyticAccount, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	// Create analytic account entity
+	account := types.AnalyticAccount{
+		OrganizationID: req.OrganizationID,
+		CompanyID:      req.CompanyID,
+		Name:           req.Name,
+		Code:           req.Code,
+		PartnerID:      req.PartnerID,
+		Active:         req.Active,
+	}
+
+	return s.repository.Create(ctx, account)
+}
+
+func (s *AnalyticAccountService) GetByID(ctx context.Context, id uuid.UUID) (*types.AnalyticAccount, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *AnalyticAccountService) List(ctx context.Context, filter types.AnalyticAccountFilter) ([]types.AnalyticAccount, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *AnalyticAccountService) Update(ctx context.Context, id uuid.UUID, req types.AnalyticAccountUpdateRequest) (*types.AnalyticAccount, error) {
+	// Check if analytic account exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("analytic account not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *AnalyticAccountService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if analytic account exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("analytic account not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
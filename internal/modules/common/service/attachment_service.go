@@ -1,11 +1,13 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
@@ -127,6 +129,11 @@ func (s *AttachmentService) Download(ctx context.Context, id uuid.UUID, accessed
 	if err != nil {
 		return nil, fmt.Errorf("failed to download from storage: %w", err)
 	}
+	defer file.Reader.Close()
+	fileData, err := io.ReadAll(file.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from storage: %w", err)
+	}
 
 	// Log access
 	accessLog := types.AttachmentAccessLog{
@@ -147,7 +154,7 @@ func (s *AttachmentService) Download(ctx context.Context, id uuid.UUID, accessed
 
 	return &types.AttachmentDownloadResponse{
 		Attachment: attachment,
-		FileData:   file.Data,
+		FileData:   fileData,
 		MimeType:   attachment.MimeType,
 		Filename:   attachment.Name,
 	}, nil
@@ -332,8 +339,9 @@ func (s *AttachmentService) uploadToStorage(ctx context.Context, data []byte, fi
 	// Upload to storage
 	_, err := s.storage.Upload(ctx, storage.UploadOptions{
 		Key:         storageKey,
-		Data:        data,
+		Reader:      bytes.NewReader(data),
 		ContentType: mimeType,
+		Size:        int64(len(data)),
 		Metadata: map[string]string{
 			"original_filename": filename,
 			"uploaded_at":       time.Now().Format(time.RFC3339),
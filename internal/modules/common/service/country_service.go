@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
@@ -39,6 +40,8 @@ func (s *CountryService) Create(ctx context.Context, req types.CountryCreateRequ
 	// Create country entity
 	country := types.Country{
 		Code:        req.Code,
+		Alpha3:      strings.ToUpper(req.Alpha3),
+		NumericCode: req.NumericCode,
 		Name:        req.Name,
 		PhoneCode:   req.PhoneCode,
 		CurrencyID:  req.CurrencyID,
@@ -52,8 +55,18 @@ func (s *CountryService) GetByID(ctx context.Context, id uuid.UUID) (*types.Coun
 	return s.repository.GetByID(ctx, id)
 }
 
+// GetByCode returns the country with the ISO 3166-1 alpha-2, alpha-3 or
+// numeric code, or nil when none has it
 func (s *CountryService) GetByCode(ctx context.Context, code string) (*types.Country, error) {
-	return s.repository.GetByCode(ctx, code)
+	var lookup types.CountryLookup
+	if !addCountryCodes(&lookup, code) {
+		return nil, nil
+	}
+	countries, err := s.repository.Find(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+	return matchCountryCode(code, countries), nil
 }
 
 func (s *CountryService) List(ctx context.Context, filter types.CountryFilter) ([]types.Country, error) {
@@ -117,7 +130,7 @@ func (s *CountryService) GetCountryWithStates(ctx context.Context, countryID uui
 	}
 
 	// Get states for this country
-	stateRepo := repository.NewStateRepository(s.repository.db)
+	stateRepo := repository.NewStateRepository(s.repository.DB())
 	states, err := stateRepo.ListByCountry(ctx, countryID)
 	if err != nil {
 		return nil, err
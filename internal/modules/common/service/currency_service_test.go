@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
@@ -49,7 +50,6 @@ func (m *MockCurrencyRepository) Delete(ctx context.Context, id uuid.UUID) error
 
 func TestCurrencyService_Create(t *testing.T) {
 	// Setup
-	_ := new(MockCurrencyRepository)
 	service := NewCurrencyService(&repository.CurrencyRepository{})
 
 	// Replace the repository with mock
@@ -115,14 +115,14 @@ func TestCurrencyService_FormatAmount(t *testing.T) {
 
 	// Create service with mock repository
 	// Note: This would require modifying the service to accept an interface
-	_ := NewCurrencyService(&repository.CurrencyRepository{})
+	_ = NewCurrencyService(&repository.CurrencyRepository{})
 
 	// Test the FormatAmount method
 	// Since we can't easily mock the repository, we'll test the logic directly
 	t.Run("should format amount correctly for before position", func(t *testing.T) {
 		// Test the formatting logic directly
 		amount := 1234.56
-		expected := "$ 1234.56"
+		expected := fmt.Sprintf("%s %.2f", testCurrency.Symbol, amount)
 
 		// This is a simplified test since we can't easily mock the repository
 		// In a real implementation, we would use dependency injection
@@ -132,7 +132,7 @@ func TestCurrencyService_FormatAmount(t *testing.T) {
 	t.Run("should format amount correctly for after position", func(t *testing.T) {
 		// Test the formatting logic directly
 		amount := 1234.56
-		expected := "1234.56 €"
+		expected := fmt.Sprintf("%.2f €", amount)
 
 		// This is a simplified test since we can't easily mock the repository
 		// In a real implementation, we would use dependency injection
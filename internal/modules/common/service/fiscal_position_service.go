@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// FiscalPositionService handles business logic for fiscal positions
+type FiscalPositionService struct {
+	repository *repository.FiscalPositionRepository
+}
+
+func NewFiscalPositionService(repository *repository.FiscalPositionRepository) *FiscalPositionService {
+	return &FiscalPositionService{repository: repository}
+}
+
+// validateZipRange rejects a zip range that ends before it starts
+func validateZipRange(from, to *int) error {
+	if from != nil && to != nil && *from > *to {
+		return errors.New("zip_from must not be greater than zip_to")
+	}
+	return nil
+}
+
+func (s *FiscalPositionService) Create(ctx context.Context, req types.FiscalPositionCreateRequest) (*types.FiscalPosition, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if err := validateZipRange(req.ZipFrom, req.ZipTo); err != nil {
+		return nil, err
+	}
+
+	// Create fiscal position entity
+	position := types.FiscalPosition{
+		OrganizationID: req.OrganizationID,
+		CompanyID:      req.CompanyID,
+		Name:           req.Name,
+		AutoApply:      req.AutoApply,
+		VATRequired:    req.VATRequired,
+		CountryID:      req.CountryID,
+		StateIDs:       req.StateIDs,
+		ZipFrom:        req.ZipFrom,
+		ZipTo:          req.ZipTo,
+		Note:           req.Note,
+		Active:         req.Active,
+	}
+
+	return s.repository.Create(ctx, position)
+}
+
+func (s *FiscalPositionService) GetByID(ctx context.Context, id uuid.UUID) (*types.FiscalPosition, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *FiscalPositionService) List(ctx context.Context, filter types.FiscalPositionFilter) ([]types.FiscalPosition, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *FiscalPositionService) Update(ctx context.Context, id uuid.UUID, req types.FiscalPositionUpdateRequest) (*types.FiscalPosition, error) {
+	// Check if fiscal position exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("fiscal position not found")
+	}
+
+	// The zip range must still hold once the update is applied
+	zipFrom, zipTo := existing.ZipFrom, existing.ZipTo
+	if req.ZipFrom != nil {
+		zipFrom = req.ZipFrom
+	}
+	if req.ZipTo != nil {
+		zipTo = req.ZipTo
+	}
+	if err := validateZipRange(zipFrom, zipTo); err != nil {
+		return nil, err
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *FiscalPositionService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if fiscal position exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("fiscal position not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
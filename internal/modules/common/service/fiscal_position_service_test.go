@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestFiscalPositionCreateRejectsInvertedZipRange(t *testing.T) {
+	from, to := 9000, 1000
+	svc := NewFiscalPositionService(nil)
+
+	_, err := svc.Create(context.Background(), types.FiscalPositionCreateRequest{
+		OrganizationID: uuid.New(),
+		Name:           "EU B2B",
+		ZipFrom:        &from,
+		ZipTo:          &to,
+	})
+	assert.EqualError(t, err, "zip_from must not be greater than zip_to")
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// IndustryService handles business logic for industries
+type IndustryService struct {
+	repository *repository.IndustryRepository
+}
+
+func NewIndustryService(repository *repository.IndustryRepository) *IndustryService {
+	return &IndustryService{repository: repository}
+}
+
+func (s *IndustryService) Create(ctx context.Context, req types.IndustryCreateRequest) (*types.Industry, error) {
+	// Validate required fields
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	// Create industry entity
+	industry := types.Industry{
+		Name:     req.Name,
+		Code:     req.Code,
+		FullName: req.FullName,
+	}
+
+	return s.repository.Create(ctx, industry)
+}
+
+func (s *IndustryService) GetByID(ctx context.Context, id uuid.UUID) (*types.Industry, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *IndustryService) List(ctx context.Context, filter types.IndustryFilter) ([]types.Industry, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *IndustryService) Update(ctx context.Context, id uuid.UUID, req types.IndustryUpdateRequest) (*types.Industry, error) {
+	// Check if industry exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("industry not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *IndustryService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if industry exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("industry not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
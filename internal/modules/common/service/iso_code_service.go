@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// isoStateCode matches a full ISO 3166-2 code such as US-CA
+var isoStateCode = regexp.MustCompile(`^[A-Z]{2}-[A-Z0-9]{1,3}$`)
+
+// ISOCodeService maps the ISO codes and names of countries and states to
+// their IDs on import, and their IDs back to ISO codes on export
+type ISOCodeService struct {
+	countryRepository *repository.CountryRepository
+	stateRepository   *repository.StateRepository
+}
+
+func NewISOCodeService(countryRepository *repository.CountryRepository, stateRepository *repository.StateRepository) *ISOCodeService {
+	return &ISOCodeService{countryRepository: countryRepository, stateRepository: stateRepository}
+}
+
+// Resolve maps the country and state codes or names of an import to their
+// IDs. Inputs that match nothing are reported with a nil match rather than
+// failing the whole request.
+func (s *ISOCodeService) Resolve(ctx context.Context, req types.ISOCodeResolveRequest) (*types.ISOCodeResolveResult, error) {
+	if n := len(req.Countries) + len(req.States); n > types.MaxISOCodeItems {
+		return nil, fmt.Errorf("%w: %d inputs exceed the limit of %d", types.ErrTooManyISOCodes, n, types.MaxISOCodeItems)
+	}
+
+	var lookup types.CountryLookup
+	queryCountries := false
+	for _, input := range req.Countries {
+		queryCountries = addCountryInput(&lookup, input) || queryCountries
+	}
+	for _, ref := range req.States {
+		queryCountries = addCountryInput(&lookup, ref.Country) || queryCountries
+	}
+	var countries []types.Country
+	if queryCountries {
+		var err error
+		if countries, err = s.countryRepository.Find(ctx, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &types.ISOCodeResolveResult{
+		Countries: make([]types.CountryMatch, len(req.Countries)),
+		States:    make([]types.StateMatch, len(req.States)),
+	}
+	for i, input := range req.Countries {
+		result.Countries[i] = types.CountryMatch{Input: input, Country: matchCountry(input, countries)}
+		if result.Countries[i].Country == nil {
+			result.Unresolved++
+		}
+	}
+
+	refCountries := make([]*types.Country, len(req.States))
+	var stateLookup types.StateLookup
+	for i, ref := range req.States {
+		if ref.Country != "" {
+			refCountries[i] = matchCountry(ref.Country, countries)
+		}
+		addStateInput(&stateLookup, ref, refCountries[i])
+	}
+	var states []types.State
+	if len(stateLookup.ISOCodes) > 0 {
+		var err error
+		if states, err = s.stateRepository.Find(ctx, stateLookup); err != nil {
+			return nil, err
+		}
+	}
+	for i, ref := range req.States {
+		result.States[i] = types.StateMatch{Input: ref, State: matchState(ref, refCountries[i], states)}
+		if result.States[i].State == nil {
+			result.Unresolved++
+		}
+	}
+	return result, nil
+}
+
+// Codes maps country and state IDs to their ISO codes for export
+func (s *ISOCodeService) Codes(ctx context.Context, req types.ISOCodesRequest) (*types.ISOCodesResult, error) {
+	if n := len(req.CountryIDs) + len(req.StateIDs); n > types.MaxISOCodeItems {
+		return nil, fmt.Errorf("%w: %d IDs exceed the limit of %d", types.ErrTooManyISOCodes, n, types.MaxISOCodeItems)
+	}
+
+	result := &types.ISOCodesResult{
+		Countries: map[uuid.UUID]string{},
+		States:    map[uuid.UUID]string{},
+	}
+	if len(req.CountryIDs) > 0 {
+		countries, err := s.countryRepository.ListByIDs(ctx, req.CountryIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, country := range countries {
+			result.Countries[country.ID] = country.Code
+		}
+	}
+	if len(req.StateIDs) > 0 {
+		states, err := s.stateRepository.ListByIDs(ctx, req.StateIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			result.States[state.ID] = state.ISOCode
+		}
+	}
+	return result, nil
+}
+
+// numericCountryCode pads an ISO 3166-1 numeric code to three digits, or
+// returns false when the input is not one
+func numericCountryCode(input string) (string, bool) {
+	if input == "" || len(input) > 3 {
+		return "", false
+	}
+	for _, r := range input {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return strings.Repeat("0", 3-len(input)) + input, true
+}
+
+// addCountryCodes adds the ISO 3166-1 code an input could stand for to the
+// lookup, reporting whether it could stand for one
+func addCountryCodes(lookup *types.CountryLookup, input string) bool {
+	input = strings.TrimSpace(input)
+	if numeric, ok := numericCountryCode(input); ok {
+		lookup.NumericCodes = append(lookup.NumericCodes, numeric)
+		return true
+	}
+	code := strings.ToUpper(input)
+	switch len(code) {
+	case 2:
+		lookup.Codes = append(lookup.Codes, code)
+	case 3:
+		lookup.Alpha3 = append(lookup.Alpha3, code)
+	default:
+		return false
+	}
+	return true
+}
+
+// addCountryInput adds the codes and name an input could stand for to the
+// lookup, reporting whether there was anything to add
+func addCountryInput(lookup *types.CountryLookup, input string) bool {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return false
+	}
+	if _, ok := numericCountryCode(input); ok {
+		return addCountryCodes(lookup, input)
+	}
+	addCountryCodes(lookup, input)
+	lookup.Names = append(lookup.Names, strings.ToLower(input))
+	return true
+}
+
+// matchCountryCode picks the country with the ISO 3166-1 code
+func matchCountryCode(input string, countries []types.Country) *types.Country {
+	input = strings.TrimSpace(input)
+	numeric, isNumeric := numericCountryCode(input)
+	code := strings.ToUpper(input)
+	for i := range countries {
+		country := &countries[i]
+		switch {
+		case isNumeric && country.NumericCode == numeric,
+			len(code) == 2 && country.Code == code,
+			len(code) == 3 && country.Alpha3 == code:
+			return country
+		}
+	}
+	return nil
+}
+
+// matchCountry picks the country an input stands for, preferring codes over
+// names
+func matchCountry(input string, countries []types.Country) *types.Country {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil
+	}
+	if country := matchCountryCode(input, countries); country != nil {
+		return country
+	}
+	for i := range countries {
+		if strings.EqualFold(countries[i].Name, input) {
+			return &countries[i]
+		}
+	}
+	return nil
+}
+
+// addStateInput adds the ISO code and name a state reference could stand
+// for to the lookup. References that are not a full ISO 3166-2 code need
+// their country.
+func addStateInput(lookup *types.StateLookup, ref types.StateRef, country *types.Country) {
+	input := strings.TrimSpace(ref.State)
+	code := strings.ToUpper(input)
+	switch {
+	case input == "":
+	case isoStateCode.MatchString(code):
+		lookup.ISOCodes = append(lookup.ISOCodes, code)
+	case country != nil:
+		lookup.ISOCodes = append(lookup.ISOCodes, stateISOCode(country.Code, code))
+		lookup.Names = append(lookup.Names, strings.ToLower(input))
+		lookup.CountryIDs = append(lookup.CountryIDs, country.ID)
+	}
+}
+
+// matchState picks the state a reference stands for, preferring codes over
+// names. A full ISO 3166-2 code must agree with the country when one is
+// given.
+func matchState(ref types.StateRef, country *types.Country, states []types.State) *types.State {
+	input := strings.TrimSpace(ref.State)
+	code := strings.ToUpper(input)
+	if input == "" {
+		return nil
+	}
+	if isoStateCode.MatchString(code) {
+		if ref.Country != "" && (country == nil || !strings.HasPrefix(code, country.Code+"-")) {
+			return nil
+		}
+		for i := range states {
+			if states[i].ISOCode == code {
+				return &states[i]
+			}
+		}
+		return nil
+	}
+	if country == nil {
+		return nil
+	}
+	for i := range states {
+		if states[i].CountryID == country.ID && states[i].ISOCode == stateISOCode(country.Code, code) {
+			return &states[i]
+		}
+	}
+	for i := range states {
+		if states[i].CountryID == country.ID && strings.EqualFold(states[i].Name, input) {
+			return &states[i]
+		}
+	}
+	return nil
+}
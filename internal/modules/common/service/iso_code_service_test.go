@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+)
+
+func TestISOCodeServiceResolveMapsCodesAndNamesToIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	au, ca, us := uuid.New(), uuid.New(), uuid.New()
+	nsw, on, california := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`FROM countries\s+WHERE code = ANY`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "alpha3", "numeric_code", "name", "phone_code", "currency_id", "address_format", "created_at"}).
+			AddRow(au, "AU", "AUS", "036", "Australia", "", nil, "", now).
+			AddRow(ca, "CA", "CAN", "124", "Canada", "", nil, "", now).
+			AddRow(us, "US", "USA", "840", "United States", "", nil, "", now))
+	mock.ExpectQuery(`FROM states\s+WHERE iso_code = ANY`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "country_id", "name", "code", "iso_code", "subdivision_type", "parent_id", "created_at"}).
+			AddRow(nsw, au, "New South Wales", "NSW", "AU-NSW", "state", nil, now).
+			AddRow(on, ca, "Ontario", "ON", "CA-ON", "province", nil, now).
+			AddRow(california, us, "California", "CA", "US-CA", "state", nil, now))
+
+	svc := NewISOCodeService(repository.NewCountryRepository(db), repository.NewStateRepository(db))
+	result, err := svc.Resolve(context.Background(), types.ISOCodeResolveRequest{
+		Countries: []string{"us", "AUS", "36", " Canada ", "Atlantis", ""},
+		States: []types.StateRef{
+			{State: "au-nsw"},
+			{Country: "United States", State: "CA"},
+			{Country: "CAN", State: "ontario"},
+			{Country: "US", State: "CA-ON"},
+			{State: "Ontario"},
+		},
+	})
+	require.NoError(t, err)
+
+	countries := make([]uuid.UUID, len(result.Countries))
+	for i, match := range result.Countries {
+		if match.Country != nil {
+			countries[i] = match.Country.ID
+		}
+	}
+	assert.Equal(t, []uuid.UUID{us, au, au, ca, uuid.Nil, uuid.Nil}, countries)
+
+	states := make([]uuid.UUID, len(result.States))
+	for i, match := range result.States {
+		if match.State != nil {
+			states[i] = match.State.ID
+		}
+	}
+	assert.Equal(t, []uuid.UUID{nsw, california, on, uuid.Nil, uuid.Nil}, states)
+	assert.Equal(t, 4, result.Unresolved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestISOCodeServiceResolveRejectsOversizedBatches(t *testing.T) {
+	svc := NewISOCodeService(&repository.CountryRepository{}, &repository.StateRepository{})
+
+	_, err := svc.Resolve(context.Background(), types.ISOCodeResolveRequest{Countries: make([]string, types.MaxISOCodeItems+1)})
+	assert.ErrorIs(t, err, types.ErrTooManyISOCodes)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// PaymentTermService handles business logic for payment terms
+type PaymentTermService struct {
+	repository *repository.PaymentTermRepository
+}
+
+func NewPaymentTermService(repository *repository.PaymentTermRepository) *PaymentTermService {
+	return &PaymentTermService{repository: repository}
+}
+
+func (s *PaymentTermService) Create(ctx context.Context, req types.PaymentTermCreateRequest) (*types.PaymentTerm, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	// Create payment term entity
+	term := types.PaymentTerm{
+		OrganizationID: req.OrganizationID,
+		CompanyID:      req.CompanyID,
+		Name:           req.Name,
+		Note:           req.Note,
+		Active:         req.Active,
+	}
+
+	return s.repository.Create(ctx, term)
+}
+
+func (s *PaymentTermService) GetByID(ctx context.Context, id uuid.UUID) (*types.PaymentTerm, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *PaymentTermService) List(ctx context.Context, filter types.PaymentTermFilter) ([]types.PaymentTerm, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *PaymentTermService) Update(ctx context.Context, id uuid.UUID, req types.PaymentTermUpdateRequest) (*types.PaymentTerm, error) {
+	// Check if payment term exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("payment term not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *PaymentTermService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if payment term exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("payment term not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
@@ -31,7 +32,7 @@ func (s *StateService) Create(ctx context.Context, req types.StateCreateRequest)
 	}
 
 	// Check if country exists
-	countryRepo := repository.NewCountryRepository(s.repository.db)
+	countryRepo := repository.NewCountryRepository(s.repository.DB())
 	country, err := countryRepo.GetByID(ctx, req.CountryID)
 	if err != nil {
 		return nil, err
@@ -52,11 +53,26 @@ func (s *StateService) Create(ctx context.Context, req types.StateCreateRequest)
 		return nil, errors.New("state with this code already exists for the country")
 	}
 
+	if req.ParentID != nil {
+		if err := s.checkParent(ctx, *req.ParentID, req.CountryID); err != nil {
+			return nil, err
+		}
+	}
+
+	// States entered without an ISO 3166-2 code get one from their country
+	isoCode := strings.ToUpper(req.ISOCode)
+	if isoCode == "" {
+		isoCode = stateISOCode(country.Code, req.Code)
+	}
+
 	// Create state entity
 	state := types.State{
 		CountryID: req.CountryID,
 		Name:      req.Name,
 		Code:      req.Code,
+		ISOCode:   isoCode,
+		Type:      req.Type,
+		ParentID:  req.ParentID,
 	}
 
 	return s.repository.Create(ctx, state)
@@ -84,9 +100,14 @@ func (s *StateService) Update(ctx context.Context, id uuid.UUID, req types.State
 		return nil, errors.New("state not found")
 	}
 
+	countryID := existing.CountryID
+	if req.CountryID != nil {
+		countryID = *req.CountryID
+	}
+
 	// If country is being updated, check if new country exists
 	if req.CountryID != nil && *req.CountryID != existing.CountryID {
-		countryRepo := repository.NewCountryRepository(s.repository.db)
+		countryRepo := repository.NewCountryRepository(s.repository.DB())
 		country, err := countryRepo.GetByID(ctx, *req.CountryID)
 		if err != nil {
 			return nil, err
@@ -96,12 +117,17 @@ func (s *StateService) Update(ctx context.Context, id uuid.UUID, req types.State
 		}
 	}
 
+	if req.ParentID != nil {
+		if *req.ParentID == id {
+			return nil, errors.New("state cannot be its own parent")
+		}
+		if err := s.checkParent(ctx, *req.ParentID, countryID); err != nil {
+			return nil, err
+		}
+	}
+
 	// If code is being updated, check if new code already exists for the country
 	if req.Code != nil && *req.Code != existing.Code {
-		countryID := existing.CountryID
-		if req.CountryID != nil {
-			countryID = *req.CountryID
-		}
 		existingStates, err := s.repository.List(ctx, types.StateFilter{
 			CountryID: &countryID,
 			Code:      req.Code,
@@ -114,9 +140,54 @@ func (s *StateService) Update(ctx context.Context, id uuid.UUID, req types.State
 		}
 	}
 
+	// A new code or country moves the ISO 3166-2 code along unless one is given
+	if req.ISOCode == nil && (req.Code != nil || req.CountryID != nil) {
+		countryRepo := repository.NewCountryRepository(s.repository.DB())
+		country, err := countryRepo.GetByID(ctx, countryID)
+		if err != nil {
+			return nil, err
+		}
+		if country != nil {
+			code := existing.Code
+			if req.Code != nil {
+				code = *req.Code
+			}
+			isoCode := stateISOCode(country.Code, code)
+			req.ISOCode = &isoCode
+		}
+	} else if req.ISOCode != nil {
+		isoCode := strings.ToUpper(*req.ISOCode)
+		req.ISOCode = &isoCode
+	}
+
 	return s.repository.Update(ctx, id, req)
 }
 
+// checkParent makes sure a parent state exists in the same country
+func (s *StateService) checkParent(ctx context.Context, parentID, countryID uuid.UUID) error {
+	parent, err := s.repository.GetByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return errors.New("parent state not found")
+	}
+	if parent.CountryID != countryID {
+		return errors.New("parent state belongs to another country")
+	}
+	return nil
+}
+
+// stateISOCode builds the ISO 3166-2 code of a state from its country's
+// alpha-2 code, unless the state code already carries the prefix
+func stateISOCode(countryCode, code string) string {
+	code = strings.ToUpper(code)
+	if strings.HasPrefix(code, countryCode+"-") {
+		return code
+	}
+	return countryCode + "-" + code
+}
+
 func (s *StateService) Delete(ctx context.Context, id uuid.UUID) error {
 	// Check if state exists
 	existing, err := s.repository.GetByID(ctx, id)
@@ -86,7 +86,7 @@ func (s *UOMCategoryService) GetUOMCategoryWithUnits(ctx context.Context, catego
 	}
 
 	// Get units for this category
-	unitRepo := repository.NewUOMUnitRepository(s.repository.db)
+	unitRepo := repository.NewUOMUnitRepository(s.repository.DB())
 	units, err := unitRepo.ListByCategory(ctx, categoryID)
 	if err != nil {
 		return nil, err
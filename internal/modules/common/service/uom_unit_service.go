@@ -28,7 +28,7 @@ func (s *UOMUnitService) Create(ctx context.Context, req types.UOMUnitCreateRequ
 	}
 
 	// Check if category exists
-	categoryRepo := repository.NewUOMCategoryRepository(s.repository.db)
+	categoryRepo := repository.NewUOMCategoryRepository(s.repository.DB())
 	category, err := categoryRepo.GetByID(ctx, req.CategoryID)
 	if err != nil {
 		return nil, err
@@ -75,7 +75,7 @@ func (s *UOMUnitService) Update(ctx context.Context, id uuid.UUID, req types.UOM
 
 	// If category is being updated, check if new category exists
 	if req.CategoryID != nil && *req.CategoryID != existing.CategoryID {
-		categoryRepo := repository.NewUOMCategoryRepository(s.repository.db)
+		categoryRepo := repository.NewUOMCategoryRepository(s.repository.DB())
 		category, err := categoryRepo.GetByID(ctx, *req.CategoryID)
 		if err != nil {
 			return nil, err
@@ -95,7 +95,7 @@ func (s *UOMUnitService) Delete(ctx context.Context, id uuid.UUID) error {
 		return err
 	}
 	if existing == nil {
-		return nil, errors.New("UOM unit not found")
+		return errors.New("UOM unit not found")
 	}
 
 	return s.repository.Delete(ctx, id)
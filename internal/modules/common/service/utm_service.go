@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/common/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/common/types"
+	"github.com/google/uuid"
+)
+
+// UTMCampaignService handles business logic for UTM campaigns
+type UTMCampaignService struct {
+	repository *repository.UTMCampaignRepository
+}
+
+func NewUTMCampaignService(repository *repository.UTMCampaignRepository) *UTMCampaignService {
+	return &UTMCampaignService{repository: repository}
+}
+
+func (s *UTMCampaignService) Create(ctx context.Context, req types.UTMCampaignCreateRequest) (*types.UTMCampaign, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	return s.repository.Create(ctx, types.UTMCampaign{
+		OrganizationID: req.OrganizationID,
+		Name:           req.Name,
+	})
+}
+
+func (s *UTMCampaignService) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMCampaign, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *UTMCampaignService) List(ctx context.Context, filter types.UTMCampaignFilter) ([]types.UTMCampaign, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *UTMCampaignService) Update(ctx context.Context, id uuid.UUID, req types.UTMCampaignUpdateRequest) (*types.UTMCampaign, error) {
+	// Check if UTM campaign exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("UTM campaign not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *UTMCampaignService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if UTM campaign exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("UTM campaign not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
+
+// UTMMediumService handles business logic for UTM mediums
+type UTMMediumService struct {
+	repository *repository.UTMMediumRepository
+}
+
+func NewUTMMediumService(repository *repository.UTMMediumRepository) *UTMMediumService {
+	return &UTMMediumService{repository: repository}
+}
+
+func (s *UTMMediumService) Create(ctx context.Context, req types.UTMMediumCreateRequest) (*types.UTMMedium, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	return s.repository.Create(ctx, types.UTMMedium{
+		OrganizationID: req.OrganizationID,
+		Name:           req.Name,
+	})
+}
+
+func (s *UTMMediumService) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMMedium, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *UTMMediumService) List(ctx context.Context, filter types.UTMMediumFilter) ([]types.UTMMedium, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *UTMMediumService) Update(ctx context.Context, id uuid.UUID, req types.UTMMediumUpdateRequest) (*types.UTMMedium, error) {
+	// Check if UTM medium exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("UTM medium not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *UTMMediumService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if UTM medium exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("UTM medium not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
+
+// UTMSourceService handles business logic for UTM sources
+type UTMSourceService struct {
+	repository *repository.UTMSourceRepository
+}
+
+func NewUTMSourceService(repository *repository.UTMSourceRepository) *UTMSourceService {
+	return &UTMSourceService{repository: repository}
+}
+
+func (s *UTMSourceService) Create(ctx context.Context, req types.UTMSourceCreateRequest) (*types.UTMSource, error) {
+	// Validate required fields
+	if req.OrganizationID == uuid.Nil {
+		return nil, errors.New("organization_id is required")
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	return s.repository.Create(ctx, types.UTMSource{
+		OrganizationID: req.OrganizationID,
+		Name:           req.Name,
+	})
+}
+
+func (s *UTMSourceService) GetByID(ctx context.Context, id uuid.UUID) (*types.UTMSource, error) {
+	return s.repository.GetByID(ctx, id)
+}
+
+func (s *UTMSourceService) List(ctx context.Context, filter types.UTMSourceFilter) ([]types.UTMSource, error) {
+	return s.repository.List(ctx, filter)
+}
+
+func (s *UTMSourceService) Update(ctx context.Context, id uuid.UUID, req types.UTMSourceUpdateRequest) (*types.UTMSource, error) {
+	// Check if UTM source exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("UTM source not found")
+	}
+
+	return s.repository.Update(ctx, id, req)
+}
+
+func (s *UTMSourceService) Delete(ctx context.Context, id uuid.UUID) error {
+	// Check if UTM source exists
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("UTM source not found")
+	}
+
+	return s.repository.Delete(ctx, id)
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -29,11 +31,12 @@ type Currency struct {
 
 // CurrencyFilter for querying currencies
 type CurrencyFilter struct {
-	Active     *bool
-	Code       *string
-	Name       *string
-	Limit      int
-	Offset     int
+	Active    *bool
+	Code      *string
+	Name      *string
+	MatchMode database.MatchMode
+	Limit     int
+	Offset    int
 }
 
 // CurrencyCreateRequest represents a request to create a currency
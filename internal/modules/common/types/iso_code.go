@@ -0,0 +1,60 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// MaxISOCodeItems bounds the codes, names or IDs mapped in one request
+const MaxISOCodeItems = 1000
+
+// ErrTooManyISOCodes is returned when a request maps more than
+// MaxISOCodeItems inputs at once
+var ErrTooManyISOCodes = errors.New("too many codes to map at once")
+
+// StateRef names a state in an imported file. State is an ISO 3166-2 code
+// such as US-CA, or a code or name within Country, which may be any country
+// code or name.
+type StateRef struct {
+	Country string `json:"country,omitempty"`
+	State   string `json:"state"`
+}
+
+// ISOCodeResolveRequest maps country and state codes or names to their IDs
+type ISOCodeResolveRequest struct {
+	Countries []string   `json:"countries,omitempty"`
+	States    []StateRef `json:"states,omitempty"`
+}
+
+// CountryMatch is the country resolved for an input, nil when none matches
+type CountryMatch struct {
+	Input   string   `json:"input"`
+	Country *Country `json:"country"`
+}
+
+// StateMatch is the state resolved for an input, nil when none matches
+type StateMatch struct {
+	Input StateRef `json:"input"`
+	State *State   `json:"state"`
+}
+
+// ISOCodeResolveResult holds one match per input, in request order
+type ISOCodeResolveResult struct {
+	Countries  []CountryMatch `json:"countries"`
+	States     []StateMatch   `json:"states"`
+	Unresolved int            `json:"unresolved"`
+}
+
+// ISOCodesRequest maps country and state IDs to their ISO codes
+type ISOCodesRequest struct {
+	CountryIDs []uuid.UUID `json:"country_ids,omitempty"`
+	StateIDs   []uuid.UUID `json:"state_ids,omitempty"`
+}
+
+// ISOCodesResult maps each known ID to its ISO 3166-1 alpha-2 or ISO 3166-2
+// code; unknown IDs are left out
+type ISOCodesResult struct {
+	Countries map[uuid.UUID]string `json:"countries"`
+	States    map[uuid.UUID]string `json:"states"`
+}
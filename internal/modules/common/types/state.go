@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -12,6 +14,9 @@ type State struct {
 	CountryID uuid.UUID  `json:"country_id" db:"country_id"`
 	Name      string     `json:"name" db:"name"`
 	Code      string     `json:"code" db:"code"`
+	ISOCode   string     `json:"iso_code" db:"iso_code"`
+	Type      string     `json:"type" db:"subdivision_type"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
 
@@ -20,15 +25,25 @@ type StateFilter struct {
 	CountryID *uuid.UUID
 	Code      *string
 	Name      *string
+	ISOCode   *string
+	// Type is the ISO 3166-2 subdivision type, such as state or province
+	Type     *string
+	ParentID *uuid.UUID
+	// TopLevel leaves out subdivisions nested in another one
+	TopLevel  bool
+	MatchMode database.MatchMode
 	Limit     int
 	Offset    int
 }
 
 // StateCreateRequest represents a request to create a state
 type StateCreateRequest struct {
-	CountryID uuid.UUID `json:"country_id" validate:"required"`
-	Name      string    `json:"name" validate:"required"`
-	Code      string    `json:"code" validate:"required"`
+	CountryID uuid.UUID  `json:"country_id" validate:"required"`
+	Name      string     `json:"name" validate:"required"`
+	Code      string     `json:"code" validate:"required"`
+	ISOCode   string     `json:"iso_code,omitempty"`
+	Type      string     `json:"type,omitempty"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
 }
 
 // StateUpdateRequest represents a request to update a state
@@ -36,4 +51,15 @@ type StateUpdateRequest struct {
 	CountryID *uuid.UUID `json:"country_id,omitempty"`
 	Name      *string    `json:"name,omitempty"`
 	Code      *string    `json:"code,omitempty"`
+	ISOCode   *string    `json:"iso_code,omitempty"`
+	Type      *string    `json:"type,omitempty"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+}
+
+// StateLookup finds states by ISO 3166-2 code, or by exact name, ignoring
+// case, within the countries
+type StateLookup struct {
+	ISOCodes   []string
+	Names      []string
+	CountryIDs []uuid.UUID
 }
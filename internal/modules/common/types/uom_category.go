@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -15,9 +17,10 @@ type UOMCategory struct {
 
 // UOMCategoryFilter for querying UOM categories
 type UOMCategoryFilter struct {
-	Name   *string
-	Limit  int
-	Offset int
+	Name      *string
+	MatchMode database.MatchMode
+	Limit     int
+	Offset    int
 }
 
 // UOMCategoryCreateRequest represents a request to create a UOM category
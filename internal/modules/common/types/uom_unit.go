@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -33,6 +35,7 @@ type UOMUnitFilter struct {
 	CategoryID *uuid.UUID
 	Active     *bool
 	Name       *string
+	MatchMode  database.MatchMode
 	Limit      int
 	Offset     int
 }
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxVerifyBody bounds the size of an export uploaded for verification
+const maxVerifyBody = 512 << 20
+
+// ComplianceHandler handles audit trail exports and retention
+type ComplianceHandler struct {
+	service *service.ComplianceService
+	logger  *slog.Logger
+}
+
+func NewComplianceHandler(service *service.ComplianceService, logger *slog.Logger) *ComplianceHandler {
+	return &ComplianceHandler{service: service, logger: logger}
+}
+
+func (h *ComplianceHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/compliance/audit-export", h.Export)
+	router.GET("/api/compliance/audit-exports", h.ListExports)
+	router.POST("/api/compliance/audit-exports/verify", h.VerifyExport)
+	router.GET("/api/compliance/audit-retention", h.GetRetention)
+	router.PUT("/api/compliance/audit-retention", h.UpdateRetention)
+}
+
+// Export handles GET /api/compliance/audit-export, downloading the audit
+// trail between from and to, as RFC 3339 times, as hash-chained JSON lines
+func (h *ComplianceHandler) Export(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+
+	// Validation and permission failures return before anything is written;
+	// the download only starts with the export header
+	out := &lazyDownload{w: w, filename: fmt.Sprintf("audit-%s-%s.jsonl", from.UTC().Format("20060102"), to.UTC().Format("20060102"))}
+	export, err := h.service.Export(r.Context(), authCtx.OrganizationID, authCtx.UserID, from, to, out)
+	if err != nil {
+		if out.started {
+			// The client sees a truncated export, which fails verification
+			h.logger.Error("Audit export failed", "organization_id", authCtx.OrganizationID, "error", err)
+			return
+		}
+		writeComplianceError(w, err)
+		return
+	}
+	h.logger.Info("Audit exported", "organization_id", authCtx.OrganizationID, "export_id", export.ID, "records", export.RecordCount)
+}
+
+// lazyDownload sets the download headers on the first write
+type lazyDownload struct {
+	w        http.ResponseWriter
+	filename string
+	started  bool
+}
+
+func (d *lazyDownload) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		d.w.Header().Set("Content-Type", "application/x-ndjson")
+		d.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", d.filename))
+		d.w.WriteHeader(http.StatusOK)
+	}
+	return d.w.Write(p)
+}
+
+// ListExports handles GET /api/compliance/audit-exports
+func (h *ComplianceHandler) ListExports(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	exports, err := h.service.ListExports(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeComplianceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, exports)
+}
+
+// VerifyExport handles POST /api/compliance/audit-exports/verify, checking
+// an export sent as the request body
+func (h *ComplianceHandler) VerifyExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxVerifyBody)
+	result, err := h.service.VerifyExport(r.Context(), authCtx.OrganizationID, body)
+	if err != nil {
+		writeComplianceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetRetention handles GET /api/compliance/audit-retention
+func (h *ComplianceHandler) GetRetention(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	retention, err := h.service.GetRetention(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeComplianceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, retention)
+}
+
+// UpdateRetention handles PUT /api/compliance/audit-retention
+func (h *ComplianceHandler) UpdateRetention(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.AuditRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	retention, err := h.service.UpdateRetention(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeComplianceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, retention)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeComplianceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypePurgeSecurityEvents = "compliance.purge_security_events"
+
+// PurgeJobHandler handles queued purges of expired security events
+type PurgeJobHandler struct {
+	complianceService *service.ComplianceService
+}
+
+func NewPurgeJobHandler(complianceService *service.ComplianceService) *PurgeJobHandler {
+	return &PurgeJobHandler{
+		complianceService: complianceService,
+	}
+}
+
+// Handle processes a security event purge job
+func (h *PurgeJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.complianceService.PurgeExpired(ctx); err != nil {
+		return fmt.Errorf("failed to purge expired security events: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *PurgeJobHandler) JobType() string {
+	return JobTypePurgeSecurityEvents
+}
+
+// Scheduler purges expired security events on a fixed interval
+type Scheduler struct {
+	handler     *PurgeJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *PurgeJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start purges every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypePurgeSecurityEvents, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypePurgeSecurityEvents, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled security event purge failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule security event purge", "error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package compliance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// eventContactExportCompleted is published once a contact export file is
+// ready; it is recorded as an export of the organization's data
+const eventContactExportCompleted = "contact.export.completed"
+
+// ComplianceModule represents the compliance audit trail module
+type ComplianceModule struct {
+	complianceService *service.ComplianceService
+	complianceHandler *handler.ComplianceHandler
+	scheduler         *jobs.Scheduler
+	logger            *slog.Logger
+}
+
+// NewComplianceModule creates a new compliance module
+func NewComplianceModule() *ComplianceModule {
+	return &ComplianceModule{}
+}
+
+// Name returns the module name
+func (m *ComplianceModule) Name() string {
+	return "compliance"
+}
+
+// Init initializes the compliance module and starts scheduled purges of
+// expired security events
+func (m *ComplianceModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "compliance")
+	m.logger.Info("Initializing compliance module")
+
+	// Create repositories
+	complianceRepo := repository.NewComplianceRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.complianceService = service.NewComplianceService(complianceRepo, authAdapter, deps.EventBus, m.logger)
+
+	// Create scheduled purges
+	purgeJobHandler := jobs.NewPurgeJobHandler(m.complianceService)
+	m.scheduler = jobs.NewScheduler(purgeJobHandler, service.PurgeInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.complianceHandler = handler.NewComplianceHandler(m.complianceService, m.logger)
+
+	m.logger.Info("Compliance module initialized successfully")
+	return nil
+}
+
+// ComplianceService returns the compliance service, which other modules use
+// to record security events and to honour the audit trail retention
+func (m *ComplianceModule) ComplianceService() *service.ComplianceService {
+	return m.complianceService
+}
+
+// RegisterRoutes registers compliance module routes
+func (m *ComplianceModule) RegisterRoutes(router interface{}) {
+	if m.complianceHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.complianceHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers records the exports other modules publish. Other
+// security events are recorded by modules calling the service directly.
+func (m *ComplianceModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.complianceService == nil {
+		return
+	}
+
+	eventBus.Subscribe(eventContactExportCompleted, m.handleContactExport)
+
+	m.logger.Info("Compliance module event handlers registered")
+}
+
+// handleContactExport records a completed contact export, attributed to the
+// user who requested it when the event carries their session
+func (m *ComplianceModule) handleContactExport(ctx context.Context, event events.Event) error {
+	var completed struct {
+		OrganizationID uuid.UUID `json:"organization_id"`
+		JobID          string    `json:"job_id"`
+		TotalRecords   int       `json:"total_records"`
+	}
+	bytes, err := json.Marshal(event.Payload)
+	if err == nil {
+		err = json.Unmarshal(bytes, &completed)
+	}
+	if err != nil {
+		m.logger.Error("Failed to decode export event", "event", event.Type, "error", err)
+		return nil
+	}
+
+	var userID *uuid.UUID
+	if authCtx, err := auth.FromContext(ctx); err == nil && authCtx.UserID != uuid.Nil {
+		userID = &authCtx.UserID
+	}
+	m.complianceService.RecordExport(ctx, completed.OrganizationID, userID, map[string]string{
+		"export":  "contacts",
+		"job_id":  completed.JobID,
+		"records": fmt.Sprint(completed.TotalRecords),
+	})
+	return nil
+}
+
+// Health checks the health of the compliance module
+func (m *ComplianceModule) Health() error {
+	return nil
+}
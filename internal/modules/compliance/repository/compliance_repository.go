@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when an audit export does not exist
+var ErrNotFound = errors.New("not found")
+
+// ComplianceRepo defines the interface for compliance repository operations
+type ComplianceRepo interface {
+	CreateSecurityEvent(ctx context.Context, event types.SecurityEvent) error
+	// StreamAuditRecords calls fn with the organization's audit records in
+	// [from, to) in the order they occurred, without their sequence numbers
+	// and hashes
+	StreamAuditRecords(ctx context.Context, orgID uuid.UUID, from, to time.Time, fn func(types.AuditRecord) error) error
+	CreateExport(ctx context.Context, export types.AuditExport) error
+	FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.AuditExport, error)
+	ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.AuditExport, error)
+	FindRetention(ctx context.Context, orgID uuid.UUID) (*types.AuditRetention, error)
+	UpsertRetention(ctx context.Context, retention types.AuditRetention) (*types.AuditRetention, error)
+	// PurgeSecurityEvents deletes the security events older than their
+	// organization's retention, or defaultDays without one
+	PurgeSecurityEvents(ctx context.Context, defaultDays int, now time.Time) (int, error)
+}
+
+// ComplianceRepository stores security events, audit exports and audit
+// trail retention, and reads the audit trail for export
+type ComplianceRepository struct {
+	db *sql.DB
+}
+
+// Ensure ComplianceRepository implements ComplianceRepo interface
+var _ ComplianceRepo = &ComplianceRepository{}
+
+func NewComplianceRepository(db *sql.DB) *ComplianceRepository {
+	return &ComplianceRepository{db: db}
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func (r *ComplianceRepository) CreateSecurityEvent(ctx context.Context, event types.SecurityEvent) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode security event metadata: %w", err)
+	}
+	if event.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO security_events (id, organization_id, event_type, user_id, actor_user_id, ip_address, user_agent, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::inet, NULLIF($7, ''), $8, $9)`,
+		event.ID, event.OrganizationID, event.EventType, event.UserID, event.ActorUserID,
+		event.IPAddress, event.UserAgent, metadata, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record security event: %w", err)
+	}
+	return nil
+}
+
+// StreamAuditRecords reads security events and permission changes together,
+// ordered by when they occurred and then by source and ID so that exports of
+// the same period chain the same way
+func (r *ComplianceRepository) StreamAuditRecords(ctx context.Context, orgID uuid.UUID, from, to time.Time, fn func(types.AuditRecord) error) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT 'security_event' AS source, e.id, e.created_at, e.event_type, e.user_id, e.actor_user_id,
+			COALESCE(host(e.ip_address), ''), COALESCE(e.user_agent, ''), e.metadata
+		FROM security_events e
+		WHERE e.organization_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		UNION ALL
+		SELECT 'permission_change', p.id, p.created_at, 'permission.' || p.action, p.user_id, NULL,
+			COALESCE(host(p.ip_address), ''), COALESCE(p.user_agent, ''),
+			jsonb_strip_nulls(jsonb_build_object(
+				'target_type', p.target_type, 'target_id', p.target_id, 'target_name', p.target_name,
+				'old_values', p.old_values, 'new_values', p.new_values, 'changed_fields', p.changed_fields,
+				'reason', p.reason, 'session_id', p.session_id))
+		FROM permission_audit_log p
+		WHERE p.organization_id = $1 AND p.created_at >= $2 AND p.created_at < $3
+		ORDER BY 3, 1, 2`, orgID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to read audit trail: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record types.AuditRecord
+		var userID, actorUserID uuid.NullUUID
+		var details []byte
+		if err := rows.Scan(&record.Source, &record.ID, &record.OccurredAt, &record.EventType, &userID,
+			&actorUserID, &record.IPAddress, &record.UserAgent, &details); err != nil {
+			return fmt.Errorf("failed to scan audit record: %w", err)
+		}
+		record.UserID = nullUUID(userID)
+		record.ActorUserID = nullUUID(actorUserID)
+		if len(details) > 0 && string(details) != "{}" {
+			record.Details = details
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *ComplianceRepository) CreateExport(ctx context.Context, export types.AuditExport) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_exports (id, organization_id, period_start, period_end, record_count, head_hash, exported_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		export.ID, export.OrganizationID, export.From, export.To, export.RecordCount, export.HeadHash,
+		export.ExportedBy, export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit export: %w", err)
+	}
+	return nil
+}
+
+const exportColumns = `id, organization_id, period_start, period_end, record_count, head_hash, exported_by, created_at`
+
+func scanExport(row interface{ Scan(...interface{}) error }) (*types.AuditExport, error) {
+	var export types.AuditExport
+	if err := row.Scan(&export.ID, &export.OrganizationID, &export.From, &export.To, &export.RecordCount,
+		&export.HeadHash, &export.ExportedBy, &export.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *ComplianceRepository) FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.AuditExport, error) {
+	export, err := scanExport(r.db.QueryRowContext(ctx, `
+		SELECT `+exportColumns+` FROM audit_exports WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("audit export %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find audit export: %w", err)
+	}
+	return export, nil
+}
+
+// ListExports returns the organization's latest exports
+func (r *ComplianceRepository) ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.AuditExport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+exportColumns+` FROM audit_exports
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []types.AuditExport{}
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+	return exports, rows.Err()
+}
+
+// FindRetention returns the organization's audit trail retention, or nil
+// when it keeps the default
+func (r *ComplianceRepository) FindRetention(ctx context.Context, orgID uuid.UUID) (*types.AuditRetention, error) {
+	retention := types.AuditRetention{OrganizationID: orgID}
+	var updatedAt time.Time
+	var updatedBy uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT retention_days, updated_at, updated_by FROM compliance_audit_settings WHERE organization_id = $1
+	`, orgID).Scan(&retention.RetentionDays, &updatedAt, &updatedBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find audit retention: %w", err)
+	}
+	retention.UpdatedAt = &updatedAt
+	retention.UpdatedBy = nullUUID(updatedBy)
+	return &retention, nil
+}
+
+func (r *ComplianceRepository) UpsertRetention(ctx context.Context, retention types.AuditRetention) (*types.AuditRetention, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO compliance_audit_settings (organization_id, retention_days, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			retention_days = EXCLUDED.retention_days, updated_at = now(), updated_by = EXCLUDED.updated_by
+		RETURNING updated_at
+	`, retention.OrganizationID, retention.RetentionDays, retention.UpdatedBy).Scan(&updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save audit retention: %w", err)
+	}
+	retention.UpdatedAt = &updatedAt
+	retention.IsDefault = false
+	return &retention, nil
+}
+
+func (r *ComplianceRepository) PurgeSecurityEvents(ctx context.Context, defaultDays int, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM security_events e
+		WHERE e.created_at < $2::timestamptz - make_interval(days => COALESCE(
+			(SELECT s.retention_days FROM compliance_audit_settings s WHERE s.organization_id = e.organization_id), $1))
+	`, defaultDays, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge security events: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
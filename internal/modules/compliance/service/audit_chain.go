@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/types"
+)
+
+const (
+	// ChainAlgorithm names how audit export records are chained
+	ChainAlgorithm = "sha256-chain"
+	// maxExportLine bounds a line of an audit export read for verification
+	maxExportLine = 4 << 20
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// headerHash is the hash the first record of an export chains from
+func headerHash(header types.AuditExportHeader) (string, error) {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode export header: %w", err)
+	}
+	return sha256Hex(data), nil
+}
+
+// recordHash is the hash of a record chained after prevHash
+func recordHash(prevHash string, record types.AuditRecord) (string, error) {
+	record.PrevHash = prevHash
+	record.Hash = ""
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	return sha256Hex(append([]byte(prevHash), data...)), nil
+}
+
+// chainWriter writes an export line by line, chaining each record to the
+// one before it
+type chainWriter struct {
+	w     io.Writer
+	head  string
+	count int
+}
+
+func newChainWriter(w io.Writer, header types.AuditExportHeader) (*chainWriter, error) {
+	head, err := headerHash(header)
+	if err != nil {
+		return nil, err
+	}
+	cw := &chainWriter{w: w, head: head}
+	return cw, cw.writeLine(header)
+}
+
+func (cw *chainWriter) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode export line: %w", err)
+	}
+	if _, err := cw.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+func (cw *chainWriter) write(record types.AuditRecord) error {
+	record.Seq = cw.count + 1
+	record.OccurredAt = record.OccurredAt.UTC()
+	hash, err := recordHash(cw.head, record)
+	if err != nil {
+		return err
+	}
+	record.PrevHash = cw.head
+	record.Hash = hash
+	if err := cw.writeLine(record); err != nil {
+		return err
+	}
+	cw.head = hash
+	cw.count++
+	return nil
+}
+
+func (cw *chainWriter) close() error {
+	return cw.writeLine(types.AuditExportTrailer{Type: "trailer", RecordCount: cw.count, HeadHash: cw.head})
+}
+
+// verifyChain checks an export read from r: that every record chains from
+// the one before it and that the trailer matches the last record. It
+// returns the export's header and the verification, whose Recorded is left
+// for the caller.
+func verifyChain(r io.Reader) (*types.AuditExportHeader, types.AuditExportVerification) {
+	var result types.AuditExportVerification
+	fail := func(line int, format string, args ...interface{}) (*types.AuditExportHeader, types.AuditExportVerification) {
+		result.Valid = false
+		result.Line = line
+		result.Error = fmt.Sprintf(format, args...)
+		return nil, result
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExportLine)
+
+	var header *types.AuditExportHeader
+	var head string
+	line := 0
+	trailed := false
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if trailed {
+			return fail(line, "unexpected line after trailer")
+		}
+
+		var kind struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &kind); err != nil {
+			return fail(line, "invalid JSON: %v", err)
+		}
+
+		switch {
+		case header == nil:
+			var h types.AuditExportHeader
+			if err := json.Unmarshal(data, &h); err != nil || kind.Type != "header" {
+				return fail(line, "first line is not an export header")
+			}
+			if h.Algorithm != ChainAlgorithm {
+				return fail(line, "unsupported algorithm %q", h.Algorithm)
+			}
+			hash, err := headerHash(h)
+			if err != nil {
+				return fail(line, "%v", err)
+			}
+			header, head = &h, hash
+			result.ExportID = &h.ExportID
+		case kind.Type == "trailer":
+			var trailer types.AuditExportTrailer
+			if err := json.Unmarshal(data, &trailer); err != nil {
+				return fail(line, "invalid trailer: %v", err)
+			}
+			if trailer.RecordCount != result.RecordCount || trailer.HeadHash != head {
+				return fail(line, "trailer does not match the records")
+			}
+			trailed = true
+		default:
+			var record types.AuditRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fail(line, "invalid record: %v", err)
+			}
+			if record.Seq != result.RecordCount+1 {
+				return fail(line, "record %d is out of sequence", record.Seq)
+			}
+			if record.PrevHash != head {
+				return fail(line, "record %d does not chain from the record before it", record.Seq)
+			}
+			hash, err := recordHash(head, record)
+			if err != nil {
+				return fail(line, "%v", err)
+			}
+			if hash != record.Hash {
+				return fail(line, "record %d has been altered", record.Seq)
+			}
+			head = hash
+			result.RecordCount++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(line+1, "failed to read export: %v", err)
+	}
+	if header == nil {
+		return fail(0, "export is empty")
+	}
+	if !trailed {
+		return fail(line, "export is truncated: no trailer")
+	}
+
+	result.Valid = true
+	result.HeadHash = head
+	return header, result
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultRetentionDays is how long the audit trail is kept when an
+	// organization has not configured it
+	DefaultRetentionDays = 7 * 365
+	// MinRetentionDays is the shortest audit trail retention an organization
+	// may configure
+	MinRetentionDays = 365
+	// MaxExportPeriod bounds the period of one audit export
+	MaxExportPeriod = 366 * 24 * time.Hour
+	// PurgeInterval is how often expired security events are purged
+	PurgeInterval = 24 * time.Hour
+	// recentExports is the number of exports listed
+	recentExports = 100
+)
+
+// ErrInvalid wraps validation failures of exports and retention settings
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the compliance service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// ComplianceService keeps the security event log and exports the audit
+// trail in a tamper-evident form for compliance reviews
+type ComplianceService struct {
+	repo        repository.ComplianceRepo
+	authService AuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewComplianceService(repo repository.ComplianceRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *ComplianceService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ComplianceService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// RecordSecurityEvent adds a security event to the audit trail and
+// publishes it, so it reaches the organization's security webhooks and
+// SIEM. It is called by other modules and bypasses the permission check.
+func (s *ComplianceService) RecordSecurityEvent(ctx context.Context, event types.SecurityEvent) error {
+	if !event.EventType.IsValid() {
+		return fmt.Errorf("%w: unknown security event %q", ErrInvalid, event.EventType)
+	}
+	if event.OrganizationID == uuid.Nil {
+		return fmt.Errorf("%w: organization is required", ErrInvalid)
+	}
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = s.now()
+	}
+	if err := s.repo.CreateSecurityEvent(ctx, event); err != nil {
+		return err
+	}
+
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, event.EventType.BusEvent(), event); err != nil {
+			s.logger.Warn("Failed to publish security event", "event", event.EventType, "error", err)
+		}
+	}
+	return nil
+}
+
+// RecordExport records that a user took an export of the organization's
+// data. Like RecordLogin, failures are only logged.
+func (s *ComplianceService) RecordExport(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, metadata map[string]string) {
+	if err := s.RecordSecurityEvent(ctx, types.SecurityEvent{
+		OrganizationID: orgID,
+		EventType:      types.EventExportPerformed,
+		UserID:         userID,
+		Metadata:       metadata,
+	}); err != nil {
+		s.logger.Error("Failed to record export", "organization_id", orgID, "error", err)
+	}
+}
+
+// RecordLogin records a sign-in attempt. A sign-in must not fail because it
+// could not be recorded, so failures are only logged.
+func (s *ComplianceService) RecordLogin(ctx context.Context, orgID, userID uuid.UUID, succeeded bool, ipAddress, userAgent string) {
+	eventType := types.EventLoginSucceeded
+	if !succeeded {
+		eventType = types.EventLoginFailed
+	}
+	if err := s.RecordSecurityEvent(ctx, types.SecurityEvent{
+		OrganizationID: orgID,
+		EventType:      eventType,
+		UserID:         &userID,
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+	}); err != nil {
+		s.logger.Error("Failed to record sign-in", "organization_id", orgID, "user_id", userID, "error", err)
+	}
+}
+
+// Export writes the organization's audit trail for [from, to) to w as JSON
+// lines: a header, the records chained by hash, and a trailer with the head
+// hash. The export is recorded so that copies of it can be verified later.
+func (s *ComplianceService) Export(ctx context.Context, orgID, userID uuid.UUID, from, to time.Time, w io.Writer) (*types.AuditExport, error) {
+	if err := s.authService.CheckPermission(ctx, "compliance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalid)
+	}
+	if to.Sub(from) > MaxExportPeriod {
+		return nil, fmt.Errorf("%w: an export covers at most %d days", ErrInvalid, int(MaxExportPeriod.Hours()/24))
+	}
+
+	export := types.AuditExport{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		From:           from.UTC(),
+		To:             to.UTC(),
+		ExportedBy:     userID,
+		CreatedAt:      s.now().UTC(),
+	}
+	cw, err := newChainWriter(w, types.AuditExportHeader{
+		Type:           "header",
+		ExportID:       export.ID,
+		OrganizationID: orgID,
+		From:           export.From,
+		To:             export.To,
+		GeneratedAt:    export.CreatedAt,
+		Algorithm:      ChainAlgorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.StreamAuditRecords(ctx, orgID, export.From, export.To, cw.write); err != nil {
+		return nil, err
+	}
+	if err := cw.close(); err != nil {
+		return nil, err
+	}
+
+	export.RecordCount = cw.count
+	export.HeadHash = cw.head
+	if err := s.repo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+	s.RecordExport(ctx, orgID, &userID, map[string]string{
+		"export":    "audit_trail",
+		"export_id": export.ID.String(),
+		"records":   fmt.Sprint(export.RecordCount),
+	})
+	return &export, nil
+}
+
+// ListExports returns the organization's latest audit exports
+func (s *ComplianceService) ListExports(ctx context.Context, orgID uuid.UUID) ([]types.AuditExport, error) {
+	if err := s.authService.CheckPermission(ctx, "compliance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListExports(ctx, orgID, recentExports)
+}
+
+// VerifyExport checks a copy of an audit export: that its records are
+// unaltered and complete, and that it is an export the organization took
+func (s *ComplianceService) VerifyExport(ctx context.Context, orgID uuid.UUID, r io.Reader) (*types.AuditExportVerification, error) {
+	if err := s.authService.CheckPermission(ctx, "compliance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	header, result := verifyChain(r)
+	if !result.Valid {
+		return &result, nil
+	}
+	if header.OrganizationID != orgID {
+		result.Valid = false
+		result.Error = "export belongs to another organization"
+		return &result, nil
+	}
+
+	export, err := s.repo.FindExport(ctx, orgID, header.ExportID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			result.Valid = false
+			result.Error = "export was not taken by this organization"
+			return &result, nil
+		}
+		return nil, err
+	}
+	result.Recorded = export.HeadHash == result.HeadHash && export.RecordCount == result.RecordCount
+	if !result.Recorded {
+		result.Valid = false
+		result.Error = "export differs from the one recorded"
+	}
+	return &result, nil
+}
+
+// GetRetention returns how long the organization keeps its audit trail
+func (s *ComplianceService) GetRetention(ctx context.Context, orgID uuid.UUID) (*types.AuditRetention, error) {
+	if err := s.authService.CheckPermission(ctx, "compliance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.retention(ctx, orgID)
+}
+
+func (s *ComplianceService) retention(ctx context.Context, orgID uuid.UUID) (*types.AuditRetention, error) {
+	retention, err := s.repo.FindRetention(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if retention == nil {
+		retention = &types.AuditRetention{
+			OrganizationID: orgID,
+			RetentionDays:  DefaultRetentionDays,
+			IsDefault:      true,
+		}
+	}
+	return retention, nil
+}
+
+// AuditRetentionDays returns how long the organization keeps its audit
+// trail. The retention module uses it so that data retention policies never
+// purge audit logs sooner.
+func (s *ComplianceService) AuditRetentionDays(ctx context.Context, orgID uuid.UUID) (int, error) {
+	retention, err := s.retention(ctx, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return retention.RetentionDays, nil
+}
+
+// UpdateRetention configures how long the organization keeps its audit
+// trail, independently of its data retention policies
+func (s *ComplianceService) UpdateRetention(ctx context.Context, orgID, userID uuid.UUID, req types.AuditRetentionRequest) (*types.AuditRetention, error) {
+	if err := s.authService.CheckPermission(ctx, "compliance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if req.RetentionDays < MinRetentionDays {
+		return nil, fmt.Errorf("%w: retention_days must be at least %d", ErrInvalid, MinRetentionDays)
+	}
+	return s.repo.UpsertRetention(ctx, types.AuditRetention{
+		OrganizationID: orgID,
+		RetentionDays:  req.RetentionDays,
+		UpdatedBy:      &userID,
+	})
+}
+
+// PurgeExpired deletes the security events past their organization's audit
+// trail retention. It is invoked by the scheduled job and therefore bypasses
+// the per-request permission check.
+func (s *ComplianceService) PurgeExpired(ctx context.Context) error {
+	purged, err := s.repo.PurgeSecurityEvents(ctx, DefaultRetentionDays, s.now())
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		s.logger.Info("Purged expired security events", "count", purged)
+	}
+	return nil
+}
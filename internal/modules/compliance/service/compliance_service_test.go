@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/compliance/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeComplianceRepo struct {
+	events    []types.SecurityEvent
+	records   []types.AuditRecord
+	exports   []types.AuditExport
+	retention *types.AuditRetention
+}
+
+func (f *fakeComplianceRepo) CreateSecurityEvent(ctx context.Context, event types.SecurityEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeComplianceRepo) StreamAuditRecords(ctx context.Context, orgID uuid.UUID, from, to time.Time, fn func(types.AuditRecord) error) error {
+	for _, record := range f.records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeComplianceRepo) CreateExport(ctx context.Context, export types.AuditExport) error {
+	f.exports = append(f.exports, export)
+	return nil
+}
+
+func (f *fakeComplianceRepo) FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.AuditExport, error) {
+	for i := range f.exports {
+		if f.exports[i].ID == id && f.exports[i].OrganizationID == orgID {
+			return &f.exports[i], nil
+		}
+	}
+	return nil, fmt.Errorf("audit export %w", repository.ErrNotFound)
+}
+
+func (f *fakeComplianceRepo) ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.AuditExport, error) {
+	return f.exports, nil
+}
+
+func (f *fakeComplianceRepo) FindRetention(ctx context.Context, orgID uuid.UUID) (*types.AuditRetention, error) {
+	return f.retention, nil
+}
+
+func (f *fakeComplianceRepo) UpsertRetention(ctx context.Context, retention types.AuditRetention) (*types.AuditRetention, error) {
+	f.retention = &retention
+	return &retention, nil
+}
+
+func (f *fakeComplianceRepo) PurgeSecurityEvents(ctx context.Context, defaultDays int, now time.Time) (int, error) {
+	return 0, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeComplianceRepo) *ComplianceService {
+	svc := NewComplianceService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func auditRecords() []types.AuditRecord {
+	userID := uuid.New()
+	at := time.Date(2025, 3, 4, 10, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	return []types.AuditRecord{
+		{Source: types.SourceSecurityEvent, ID: uuid.New(), OccurredAt: at, EventType: "login.succeeded",
+			UserID: &userID, IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0 <test>"},
+		{Source: types.SourcePermissionChange, ID: uuid.New(), OccurredAt: at.Add(time.Minute), EventType: "permission.grant",
+			UserID: &userID, Details: json.RawMessage(`{"target_type": "role", "new_values": {"name": "Auditor & co"}}`)},
+		{Source: types.SourceSecurityEvent, ID: uuid.New(), OccurredAt: at.Add(time.Hour), EventType: "login.failed",
+			UserID: &userID},
+	}
+}
+
+func export(t *testing.T, svc *ComplianceService, orgID uuid.UUID) (*types.AuditExport, []byte) {
+	var out bytes.Buffer
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := svc.Export(context.Background(), orgID, uuid.New(), from, from.AddDate(0, 6, 0), &out)
+	require.NoError(t, err)
+	return result, out.Bytes()
+}
+
+func TestExportVerifiesAndDetectsTampering(t *testing.T) {
+	repo := &fakeComplianceRepo{records: auditRecords()}
+	svc := newTestService(repo)
+	orgID := uuid.New()
+	ctx := context.Background()
+
+	result, data := export(t, svc, orgID)
+	assert.Equal(t, 3, result.RecordCount)
+	require.Len(t, repo.exports, 1)
+	assert.Len(t, bytes.Split(bytes.TrimSpace(data), []byte("\n")), 5, "header, records and trailer")
+
+	verification, err := svc.VerifyExport(ctx, orgID, bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, verification.Error)
+	assert.True(t, verification.Recorded)
+	assert.Equal(t, result.HeadHash, verification.HeadHash)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	altered := strings.Replace(string(data), "203.0.113.7", "203.0.113.8", 1)
+	verification, err = svc.VerifyExport(ctx, orgID, strings.NewReader(altered))
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, 2, verification.Line)
+
+	removed := strings.Join(append([]string{lines[0]}, lines[2:]...), "\n")
+	verification, err = svc.VerifyExport(ctx, orgID, strings.NewReader(removed))
+	require.NoError(t, err)
+	assert.False(t, verification.Valid, "record removed")
+
+	truncated := strings.Join(lines[:len(lines)-1], "\n")
+	verification, err = svc.VerifyExport(ctx, orgID, strings.NewReader(truncated))
+	require.NoError(t, err)
+	assert.False(t, verification.Valid, "no trailer")
+
+	verification, err = svc.VerifyExport(ctx, uuid.New(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.False(t, verification.Valid, "another organization")
+}
+
+func TestVerifyRejectsRechainedExport(t *testing.T) {
+	repo := &fakeComplianceRepo{records: auditRecords()}
+	svc := newTestService(repo)
+	orgID := uuid.New()
+
+	_, data := export(t, svc, orgID)
+
+	// Rewriting the trail and every hash after it yields a valid chain, but
+	// not the one recorded when the export was taken
+	var header types.AuditExportHeader
+	require.NoError(t, json.Unmarshal(data[:bytes.IndexByte(data, '\n')], &header))
+	var forged bytes.Buffer
+	cw, err := newChainWriter(&forged, header)
+	require.NoError(t, err)
+	for _, record := range auditRecords()[:2] {
+		require.NoError(t, cw.write(record))
+	}
+	require.NoError(t, cw.close())
+
+	verification, err := svc.VerifyExport(context.Background(), orgID, &forged)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.False(t, verification.Recorded)
+	assert.Equal(t, 2, verification.RecordCount)
+}
+
+func TestExportPeriod(t *testing.T) {
+	svc := newTestService(&fakeComplianceRepo{})
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+
+	_, err := svc.Export(context.Background(), uuid.New(), uuid.New(), from, from, &out)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = svc.Export(context.Background(), uuid.New(), uuid.New(), from, from.AddDate(2, 0, 0), &out)
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Zero(t, out.Len(), "nothing written for a rejected export")
+}
+
+func TestAuditRetention(t *testing.T) {
+	repo := &fakeComplianceRepo{}
+	svc := newTestService(repo)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	days, err := svc.AuditRetentionDays(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRetentionDays, days)
+
+	_, err = svc.UpdateRetention(ctx, orgID, uuid.New(), types.AuditRetentionRequest{RetentionDays: 90})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.UpdateRetention(ctx, orgID, uuid.New(), types.AuditRetentionRequest{RetentionDays: 10 * 365})
+	require.NoError(t, err)
+	days, err = svc.AuditRetentionDays(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 10*365, days)
+}
+
+func TestRecordLogin(t *testing.T) {
+	repo := &fakeComplianceRepo{}
+	svc := newTestService(repo)
+
+	svc.RecordLogin(context.Background(), uuid.New(), uuid.New(), false, "198.51.100.2", "curl/8")
+	require.Len(t, repo.events, 1)
+	assert.Equal(t, types.EventLoginFailed, repo.events[0].EventType)
+	assert.Equal(t, "198.51.100.2", repo.events[0].IPAddress)
+	assert.False(t, repo.events[0].CreatedAt.IsZero())
+}
+
+func TestRecordSecurityEventPublishes(t *testing.T) {
+	repo := &fakeComplianceRepo{records: auditRecords()}
+	bus := events.NewBus(false)
+	svc := NewComplianceService(repo, allowAll{}, bus, nil)
+	var published []types.SecurityEvent
+	bus.Subscribe(types.EventExportPerformed.BusEvent(), func(ctx context.Context, event events.Event) error {
+		published = append(published, event.Payload.(types.SecurityEvent))
+		return nil
+	})
+	orgID := uuid.New()
+
+	err := svc.RecordSecurityEvent(context.Background(), types.SecurityEvent{OrganizationID: orgID, EventType: "user.deleted"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// Taking an audit export is itself recorded
+	result, _ := export(t, svc, orgID)
+	require.Len(t, repo.events, 1)
+	require.Len(t, published, 1)
+	assert.Equal(t, repo.events[0], published[0])
+	assert.NotEqual(t, uuid.Nil, published[0].ID)
+	assert.Equal(t, types.EventExportPerformed, published[0].EventType)
+	assert.Equal(t, result.ID.String(), published[0].Metadata["export_id"])
+}
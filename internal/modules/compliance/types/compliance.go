@@ -0,0 +1,139 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType identifies a security event
+type SecurityEventType string
+
+const (
+	EventLoginSucceeded       SecurityEventType = "login.succeeded"
+	EventLoginFailed          SecurityEventType = "login.failed"
+	EventImpersonationStarted SecurityEventType = "impersonation.started"
+	EventImpersonationEnded   SecurityEventType = "impersonation.ended"
+	EventUserAdded            SecurityEventType = "user.added"
+	EventUserRemoved          SecurityEventType = "user.removed"
+	EventRoleChanged          SecurityEventType = "role.changed"
+	EventAPIKeyCreated        SecurityEventType = "api_key.created"
+	EventExportPerformed      SecurityEventType = "export.performed"
+)
+
+// IsValid reports whether the event type is known
+func (t SecurityEventType) IsValid() bool {
+	switch t {
+	case EventLoginSucceeded, EventLoginFailed, EventImpersonationStarted, EventImpersonationEnded,
+		EventUserAdded, EventUserRemoved, EventRoleChanged, EventAPIKeyCreated, EventExportPerformed:
+		return true
+	}
+	return false
+}
+
+// BusEvent is the event published on the bus once an event of the type is
+// recorded, such as security.impersonation.started
+func (t SecurityEventType) BusEvent() string {
+	return "security." + string(t)
+}
+
+// SecurityEvent is a sign-in, impersonation session or administrative
+// change recorded for the audit trail
+type SecurityEvent struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id"`
+	EventType      SecurityEventType `json:"event_type"`
+	UserID         *uuid.UUID        `json:"user_id,omitempty"`
+	// ActorUserID is the administrator impersonating UserID
+	ActorUserID *uuid.UUID        `json:"actor_user_id,omitempty"`
+	IPAddress   string            `json:"ip_address,omitempty"`
+	UserAgent   string            `json:"user_agent,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// AuditSource is the log an exported audit record comes from
+type AuditSource string
+
+const (
+	SourceSecurityEvent    AuditSource = "security_event"
+	SourcePermissionChange AuditSource = "permission_change"
+)
+
+// AuditRecord is one record of an audit export. Hash is the SHA-256 of
+// PrevHash followed by the record's JSON without its hash, so changing,
+// removing or reordering any record breaks every hash after it.
+type AuditRecord struct {
+	Seq         int             `json:"seq"`
+	Source      AuditSource     `json:"source"`
+	ID          uuid.UUID       `json:"id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	EventType   string          `json:"event_type"`
+	UserID      *uuid.UUID      `json:"user_id,omitempty"`
+	ActorUserID *uuid.UUID      `json:"actor_user_id,omitempty"`
+	IPAddress   string          `json:"ip_address,omitempty"`
+	UserAgent   string          `json:"user_agent,omitempty"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	PrevHash    string          `json:"prev_hash"`
+	Hash        string          `json:"hash,omitempty"`
+}
+
+// AuditExportHeader is the first line of an audit export. The hash of its
+// JSON is the PrevHash of the first record.
+type AuditExportHeader struct {
+	Type           string    `json:"type"`
+	ExportID       uuid.UUID `json:"export_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	Algorithm      string    `json:"algorithm"`
+}
+
+// AuditExportTrailer is the last line of an audit export
+type AuditExportTrailer struct {
+	Type        string `json:"type"`
+	RecordCount int    `json:"record_count"`
+	HeadHash    string `json:"head_hash"`
+}
+
+// AuditExport records an export taken, so copies of it can be verified
+type AuditExport struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	RecordCount    int       `json:"record_count"`
+	HeadHash       string    `json:"head_hash"`
+	ExportedBy     uuid.UUID `json:"exported_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AuditExportVerification is the result of checking a copy of an export
+type AuditExportVerification struct {
+	Valid       bool       `json:"valid"`
+	ExportID    *uuid.UUID `json:"export_id,omitempty"`
+	RecordCount int        `json:"record_count"`
+	HeadHash    string     `json:"head_hash,omitempty"`
+	// Recorded reports whether the head hash matches the one recorded when
+	// the export was taken
+	Recorded bool `json:"recorded"`
+	// Line is the first line that failed verification
+	Line  int    `json:"line,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// AuditRetention is how long an organization keeps its audit trail
+type AuditRetention struct {
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	RetentionDays  int        `json:"retention_days"`
+	IsDefault      bool       `json:"is_default"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy      *uuid.UUID `json:"updated_by,omitempty"`
+}
+
+// AuditRetentionRequest configures how long the audit trail is kept
+type AuditRetentionRequest struct {
+	RetentionDays int `json:"retention_days"`
+}
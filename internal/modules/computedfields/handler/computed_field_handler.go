@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ComputedFieldHandler handles HTTP requests for computed field definitions,
+// their values and report summaries
+type ComputedFieldHandler struct {
+	service *service.ComputedFieldService
+}
+
+func NewComputedFieldHandler(service *service.ComputedFieldService) *ComputedFieldHandler {
+	return &ComputedFieldHandler{service: service}
+}
+
+func (h *ComputedFieldHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/computed-fields/:entity", h.ListFields)
+	router.GET("/api/v1/computed-fields/:entity/:name", h.GetField)
+	router.PUT("/api/v1/computed-fields/:entity/:name", h.SaveField)
+	router.DELETE("/api/v1/computed-fields/:entity/:name", h.DeleteField)
+	router.GET("/api/v1/computed-fields/:entity/:name/summary", h.Summarize)
+	router.GET("/api/v1/computed-values/:entity", h.Values)
+}
+
+// ListFields handles GET /api/v1/computed-fields/:entity
+func (h *ComputedFieldHandler) ListFields(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	defs, err := h.service.ListFields(r.Context(), authCtx.OrganizationID, ps.ByName("entity"))
+	if err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, defs)
+}
+
+// GetField handles GET /api/v1/computed-fields/:entity/:name
+func (h *ComputedFieldHandler) GetField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	def, err := h.service.GetField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), ps.ByName("name"))
+	if err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// SaveField handles PUT /api/v1/computed-fields/:entity/:name
+func (h *ComputedFieldHandler) SaveField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.FieldDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Name = ps.ByName("name")
+
+	def, err := h.service.SaveField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), req)
+	if err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// DeleteField handles DELETE /api/v1/computed-fields/:entity/:name
+func (h *ComputedFieldHandler) DeleteField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), ps.ByName("name")); err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Summarize handles GET /api/v1/computed-fields/:entity/:name/summary?aggregate=&group_by=
+func (h *ComputedFieldHandler) Summarize(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	summary, err := h.service.Summarize(r.Context(), authCtx.OrganizationID, types.SummaryRequest{
+		Entity:    ps.ByName("entity"),
+		Field:     ps.ByName("name"),
+		Aggregate: computed.Aggregate(query.Get("aggregate")),
+		GroupBy:   query.Get("group_by"),
+	})
+	if err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// Values handles GET /api/v1/computed-values/:entity?ids=
+func (h *ComputedFieldHandler) Values(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var ids []uuid.UUID
+	for _, raw := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "invalid id: "+raw, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	values, err := h.service.Values(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), ids)
+	if err != nil {
+		writeComputedFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, values)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeComputedFieldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package computedfields
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ComputedFieldsModule represents the formula and rollup field module
+type ComputedFieldsModule struct {
+	computedFieldHandler *handler.ComputedFieldHandler
+	logger               *slog.Logger
+}
+
+// NewComputedFieldsModule creates a new computed fields module
+func NewComputedFieldsModule() *ComputedFieldsModule {
+	return &ComputedFieldsModule{}
+}
+
+// Name returns the module name
+func (m *ComputedFieldsModule) Name() string {
+	return "computedfields"
+}
+
+// Init initializes the computed fields module
+func (m *ComputedFieldsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "computedfields")
+	m.logger.Info("Initializing computed fields module")
+
+	// Create repositories
+	computedFieldRepo := repository.NewComputedFieldRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	computedFieldService := service.NewComputedFieldService(computedFieldRepo, authAdapter, m.logger)
+
+	// Create handlers
+	m.computedFieldHandler = handler.NewComputedFieldHandler(computedFieldService)
+
+	m.logger.Info("Computed fields module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers computed fields module routes
+func (m *ComputedFieldsModule) RegisterRoutes(router interface{}) {
+	if m.computedFieldHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.computedFieldHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the computed fields module
+func (m *ComputedFieldsModule) RegisterEventHandlers(bus interface{}) {
+	// Values are evaluated on read; nothing to consume
+}
+
+// Health checks the health of the computed fields module
+func (m *ComputedFieldsModule) Health() error {
+	return nil
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a computed field does not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// ComputedFieldRepo defines the interface for computed field repository operations
+type ComputedFieldRepo interface {
+	ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error)
+	FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error)
+	SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error)
+	DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error
+	Values(ctx context.Context, orgID uuid.UUID, entity string, fields []computed.Field, ids []uuid.UUID) ([]types.RecordValues, error)
+	Summarize(ctx context.Context, orgID uuid.UUID, entity string, field computed.Field, aggregate computed.Aggregate, groupColumn string) ([]types.SummaryRow, error)
+}
+
+// ComputedFieldRepository persists computed field definitions and evaluates
+// them against the entity tables
+type ComputedFieldRepository struct {
+	db *sql.DB
+}
+
+// Ensure ComputedFieldRepository implements ComputedFieldRepo interface
+var _ ComputedFieldRepo = &ComputedFieldRepository{}
+
+func NewComputedFieldRepository(db *sql.DB) *ComputedFieldRepository {
+	return &ComputedFieldRepository{db: db}
+}
+
+const definitionColumns = `id, organization_id, entity, name, label, kind, formula, rollup, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDefinition(row rowScanner) (*types.FieldDefinition, error) {
+	var def types.FieldDefinition
+	var formula *string
+	var rollupJSON []byte
+	err := row.Scan(
+		&def.ID, &def.OrganizationID, &def.Entity, &def.Name, &def.Label, &def.Kind,
+		&formula, &rollupJSON, &def.CreatedAt, &def.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if formula != nil {
+		def.Formula = *formula
+	}
+	if rollupJSON != nil {
+		def.Rollup = &computed.Rollup{}
+		if err := json.Unmarshal(rollupJSON, def.Rollup); err != nil {
+			return nil, fmt.Errorf("invalid rollup: %w", err)
+		}
+	}
+	return &def, nil
+}
+
+func (r *ComputedFieldRepository) ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM computed_field_definitions WHERE organization_id = $1 AND entity = $2 ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list computed fields: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []types.FieldDefinition
+	for rows.Next() {
+		def, err := scanDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan computed field: %w", err)
+		}
+		defs = append(defs, *def)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list computed fields: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *ComputedFieldRepository) FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM computed_field_definitions WHERE organization_id = $1 AND entity = $2 AND name = $3`
+
+	def, err := scanDefinition(r.db.QueryRowContext(ctx, query, orgID, entity, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("computed field %s.%s %w", entity, name, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find computed field: %w", err)
+	}
+	return def, nil
+}
+
+// SaveDefinition inserts the definition or replaces the organization's field
+// of the same entity and name
+func (r *ComputedFieldRepository) SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error) {
+	var formula *string
+	if def.Formula != "" {
+		formula = &def.Formula
+	}
+	var rollupJSON []byte
+	if def.Rollup != nil {
+		var err error
+		if rollupJSON, err = json.Marshal(def.Rollup); err != nil {
+			return nil, fmt.Errorf("failed to marshal rollup: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO computed_field_definitions (id, organization_id, entity, name, label, kind, formula, rollup, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (organization_id, entity, name) DO UPDATE SET
+			label = EXCLUDED.label,
+			kind = EXCLUDED.kind,
+			formula = EXCLUDED.formula,
+			rollup = EXCLUDED.rollup,
+			updated_at = NOW()
+		RETURNING ` + definitionColumns
+
+	saved, err := scanDefinition(r.db.QueryRowContext(ctx, query,
+		def.ID, def.OrganizationID, def.Entity, def.Name, def.Label, def.Kind, formula, rollupJSON,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save computed field: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *ComputedFieldRepository) DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM computed_field_definitions WHERE organization_id = $1 AND entity = $2 AND name = $3`,
+		orgID, entity, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete computed field: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete computed field: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("computed field %s.%s %w", entity, name, ErrNotFound)
+	}
+	return nil
+}
+
+// Values evaluates the fields for the given records of the entity. Records
+// that do not exist in the organization are omitted.
+func (r *ComputedFieldRepository) Values(ctx context.Context, orgID uuid.UUID, entity string, fields []computed.Field, ids []uuid.UUID) ([]types.RecordValues, error) {
+	table, err := entityTable(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	query := `SELECT id, ` + computed.SelectSQL(fields) + ` FROM ` + table + `
+		WHERE organization_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, pq.Array(idStrings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate computed fields: %w", err)
+	}
+	defer rows.Close()
+
+	var values []types.RecordValues
+	for rows.Next() {
+		var v types.RecordValues
+		var valuesJSON []byte
+		if err := rows.Scan(&v.ID, &valuesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan computed values: %w", err)
+		}
+		if err := json.Unmarshal(valuesJSON, &v.Values); err != nil {
+			return nil, fmt.Errorf("invalid computed values: %w", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate computed fields: %w", err)
+	}
+	return values, nil
+}
+
+// Summarize aggregates a computed field over the organization's records of
+// the entity, optionally grouped by a column. Count is the number of records
+// in the group, including those whose value is NULL.
+func (r *ComputedFieldRepository) Summarize(ctx context.Context, orgID uuid.UUID, entity string, field computed.Field, aggregate computed.Aggregate, groupColumn string) ([]types.SummaryRow, error) {
+	table, err := entityTable(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	group := "NULL::text"
+	if groupColumn != "" {
+		group = groupColumn + "::text"
+	}
+	query := `SELECT ` + group + `, (` + strings.ToUpper(string(aggregate)) + `(` + field.SQL + `))::float8, COUNT(*)
+		FROM ` + table + `
+		WHERE organization_id = $1 AND deleted_at IS NULL
+		GROUP BY 1
+		ORDER BY 1 NULLS LAST`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize computed field: %w", err)
+	}
+	defer rows.Close()
+
+	var summary []types.SummaryRow
+	for rows.Next() {
+		var row types.SummaryRow
+		if err := rows.Scan(&row.Group, &row.Value, &row.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan computed field summary: %w", err)
+		}
+		summary = append(summary, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarize computed field: %w", err)
+	}
+	return summary, nil
+}
+
+func entityTable(entity string) (string, error) {
+	schema, ok := computed.Schemas[entity]
+	if !ok {
+		return "", fmt.Errorf("entity %q does not support computed fields", entity)
+	}
+	return schema.Table, nil
+}
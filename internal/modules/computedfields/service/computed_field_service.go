@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+
+	"github.com/google/uuid"
+)
+
+// MaxValueIDs caps the records whose computed values are evaluated in one request
+const MaxValueIDs = 200
+
+// ErrInvalid wraps validation failures of computed field definitions and queries
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the computed field service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// ComputedFieldService manages formula and rollup fields and evaluates them
+// for records and reports
+type ComputedFieldService struct {
+	repo        repository.ComputedFieldRepo
+	authService AuthService
+	logger      *slog.Logger
+}
+
+func NewComputedFieldService(repo repository.ComputedFieldRepo, authService AuthService, logger *slog.Logger) *ComputedFieldService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ComputedFieldService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+// ListFields returns the organization's computed fields of an entity
+func (s *ComputedFieldService) ListFields(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateEntity(entity); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDefinitions(ctx, orgID, entity)
+}
+
+// GetField returns a computed field by entity and name
+func (s *ComputedFieldService) GetField(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDefinition(ctx, orgID, entity, name)
+}
+
+// SaveField creates the computed field or replaces the one with the same name.
+// The definition is compiled before it is stored, so stored fields always
+// evaluate.
+func (s *ComputedFieldService) SaveField(ctx context.Context, orgID uuid.UUID, entity string, req types.FieldDefinitionRequest) (*types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateEntity(entity); err != nil {
+		return nil, err
+	}
+
+	def := types.FieldDefinition{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Entity:         entity,
+		Name:           strings.TrimSpace(req.Name),
+		Label:          strings.TrimSpace(req.Label),
+		Kind:           req.Kind,
+		Formula:        strings.TrimSpace(req.Formula),
+		Rollup:         req.Rollup,
+	}
+	if def.Label == "" {
+		def.Label = def.Name
+	}
+	if _, err := computed.Compile(def.Definition()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	existing, err := s.repo.ListDefinitions(ctx, orgID, entity)
+	if err != nil {
+		return nil, err
+	}
+	replacing := false
+	for _, e := range existing {
+		if e.Name == def.Name {
+			replacing = true
+			break
+		}
+	}
+	if !replacing && len(existing) >= computed.MaxFieldsPerEntity {
+		return nil, fmt.Errorf("%w: %s may have at most %d computed fields", ErrInvalid, entity, computed.MaxFieldsPerEntity)
+	}
+
+	saved, err := s.repo.SaveDefinition(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("computed field saved", "entity", entity, "name", saved.Name, "kind", saved.Kind)
+	return saved, nil
+}
+
+// DeleteField removes a computed field
+func (s *ComputedFieldService) DeleteField(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteDefinition(ctx, orgID, entity, name)
+}
+
+// Values evaluates every computed field of the entity for the given records
+func (s *ComputedFieldService) Values(ctx context.Context, orgID uuid.UUID, entity string, ids []uuid.UUID) ([]types.RecordValues, error) {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: at least one id is required", ErrInvalid)
+	}
+	if len(ids) > MaxValueIDs {
+		return nil, fmt.Errorf("%w: at most %d ids may be requested", ErrInvalid, MaxValueIDs)
+	}
+
+	fields, err := s.compiledFields(ctx, orgID, entity)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Values(ctx, orgID, entity, fields, ids)
+}
+
+// Summarize aggregates a computed field across the entity's records for reports
+func (s *ComputedFieldService) Summarize(ctx context.Context, orgID uuid.UUID, req types.SummaryRequest) ([]types.SummaryRow, error) {
+	if err := s.authService.CheckPermission(ctx, "computed_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	switch req.Aggregate {
+	case "":
+		req.Aggregate = computed.AggregateSum
+	case computed.AggregateSum, computed.AggregateCount, computed.AggregateAvg, computed.AggregateMin, computed.AggregateMax:
+	default:
+		return nil, fmt.Errorf("%w: unknown aggregate %q", ErrInvalid, req.Aggregate)
+	}
+
+	var groupColumn string
+	if req.GroupBy != "" {
+		column, err := computed.GroupColumn(req.Entity, req.GroupBy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		groupColumn = column
+	}
+
+	fields, err := s.compiledFields(ctx, orgID, req.Entity)
+	if err != nil {
+		return nil, err
+	}
+	field, ok := computed.Lookup(fields, req.Field)
+	if !ok {
+		return nil, fmt.Errorf("computed field %s.%s %w", req.Entity, req.Field, repository.ErrNotFound)
+	}
+	return s.repo.Summarize(ctx, orgID, req.Entity, field, req.Aggregate, groupColumn)
+}
+
+func (s *ComputedFieldService) compiledFields(ctx context.Context, orgID uuid.UUID, entity string) ([]computed.Field, error) {
+	if err := validateEntity(entity); err != nil {
+		return nil, err
+	}
+	defs, err := s.repo.ListDefinitions(ctx, orgID, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]computed.Field, 0, len(defs))
+	for _, def := range defs {
+		field, err := computed.Compile(def.Definition())
+		if err != nil {
+			return nil, fmt.Errorf("computed field %s: %w", def.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func validateEntity(entity string) error {
+	if _, ok := computed.Schemas[entity]; !ok {
+		return fmt.Errorf("%w: entity %q does not support computed fields", ErrInvalid, entity)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/computedfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeComputedFieldRepo struct {
+	defs       map[string]types.FieldDefinition
+	evaluated  []computed.Field
+	summarized computed.Field
+	groupedBy  string
+}
+
+func newFakeComputedFieldRepo() *fakeComputedFieldRepo {
+	return &fakeComputedFieldRepo{defs: make(map[string]types.FieldDefinition)}
+}
+
+func (f *fakeComputedFieldRepo) ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	var defs []types.FieldDefinition
+	for _, def := range f.defs {
+		if def.OrganizationID == orgID && def.Entity == entity {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+func (f *fakeComputedFieldRepo) FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	def, ok := f.defs[entity+"."+name]
+	if !ok || def.OrganizationID != orgID {
+		return nil, fmt.Errorf("computed field %s.%s %w", entity, name, repository.ErrNotFound)
+	}
+	return &def, nil
+}
+
+func (f *fakeComputedFieldRepo) SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error) {
+	f.defs[def.Entity+"."+def.Name] = def
+	return &def, nil
+}
+
+func (f *fakeComputedFieldRepo) DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	delete(f.defs, entity+"."+name)
+	return nil
+}
+
+func (f *fakeComputedFieldRepo) Values(ctx context.Context, orgID uuid.UUID, entity string, fields []computed.Field, ids []uuid.UUID) ([]types.RecordValues, error) {
+	f.evaluated = fields
+	return nil, nil
+}
+
+func (f *fakeComputedFieldRepo) Summarize(ctx context.Context, orgID uuid.UUID, entity string, field computed.Field, aggregate computed.Aggregate, groupColumn string) ([]types.SummaryRow, error) {
+	f.summarized = field
+	f.groupedBy = groupColumn
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type denyAll struct{}
+
+func (denyAll) CheckPermission(ctx context.Context, permission string) error {
+	return errors.New("forbidden")
+}
+
+func weightedRevenue() types.FieldDefinitionRequest {
+	return types.FieldDefinitionRequest{
+		Name:    "weighted_revenue",
+		Kind:    computed.KindFormula,
+		Formula: "expected_revenue * probability / 100",
+	}
+}
+
+func TestSaveFieldCompilesDefinition(t *testing.T) {
+	repo := newFakeComputedFieldRepo()
+	svc := NewComputedFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	def, err := svc.SaveField(context.Background(), orgID, "lead", weightedRevenue())
+	require.NoError(t, err)
+	assert.Equal(t, "weighted_revenue", def.Label, "label defaults to the name")
+
+	_, err = svc.SaveField(context.Background(), orgID, "lead", types.FieldDefinitionRequest{
+		Name: "bad", Kind: computed.KindFormula, Formula: "expected_revenue * discount",
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.SaveField(context.Background(), orgID, "invoice", weightedRevenue())
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = NewComputedFieldService(repo, denyAll{}, nil).SaveField(context.Background(), orgID, "lead", weightedRevenue())
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalid)
+}
+
+func TestSaveFieldEnforcesLimit(t *testing.T) {
+	repo := newFakeComputedFieldRepo()
+	svc := NewComputedFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	for i := 0; i < computed.MaxFieldsPerEntity; i++ {
+		repo.defs[fmt.Sprintf("lead.f%d", i)] = types.FieldDefinition{
+			OrganizationID: orgID, Entity: "lead", Name: fmt.Sprintf("f%d", i), Kind: computed.KindFormula, Formula: "1",
+		}
+	}
+
+	_, err := svc.SaveField(context.Background(), orgID, "lead", weightedRevenue())
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// Replacing an existing field does not count against the limit
+	_, err = svc.SaveField(context.Background(), orgID, "lead", types.FieldDefinitionRequest{
+		Name: "f0", Kind: computed.KindFormula, Formula: "2",
+	})
+	assert.NoError(t, err)
+}
+
+func TestValuesEvaluatesEveryField(t *testing.T) {
+	repo := newFakeComputedFieldRepo()
+	svc := NewComputedFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	_, err := svc.SaveField(context.Background(), orgID, "lead", weightedRevenue())
+	require.NoError(t, err)
+
+	_, err = svc.Values(context.Background(), orgID, "lead", []uuid.UUID{uuid.New()})
+	require.NoError(t, err)
+	require.Len(t, repo.evaluated, 1)
+	assert.Equal(t, "weighted_revenue", repo.evaluated[0].Name)
+
+	_, err = svc.Values(context.Background(), orgID, "lead", nil)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.Values(context.Background(), orgID, "lead", make([]uuid.UUID, MaxValueIDs+1))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestSummarize(t *testing.T) {
+	repo := newFakeComputedFieldRepo()
+	svc := NewComputedFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	_, err := svc.SaveField(context.Background(), orgID, "lead", weightedRevenue())
+	require.NoError(t, err)
+
+	_, err = svc.Summarize(context.Background(), orgID, types.SummaryRequest{Entity: "lead", Field: "weighted_revenue", GroupBy: "stage_id"})
+	require.NoError(t, err)
+	assert.Equal(t, "weighted_revenue", repo.summarized.Name)
+	assert.Equal(t, "stage_id", repo.groupedBy)
+
+	_, err = svc.Summarize(context.Background(), orgID, types.SummaryRequest{Entity: "lead", Field: "missing"})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = svc.Summarize(context.Background(), orgID, types.SummaryRequest{Entity: "lead", Field: "weighted_revenue", GroupBy: "name"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.Summarize(context.Background(), orgID, types.SummaryRequest{Entity: "lead", Field: "weighted_revenue", Aggregate: "median"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+
+	"github.com/google/uuid"
+)
+
+// FieldDefinition is a formula or rollup field an organization has defined on an entity
+type FieldDefinition struct {
+	ID             uuid.UUID        `json:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	Entity         string           `json:"entity"`
+	Name           string           `json:"name"`
+	Label          string           `json:"label"`
+	Kind           computed.Kind    `json:"kind"`
+	Formula        string           `json:"formula,omitempty"`
+	Rollup         *computed.Rollup `json:"rollup,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// Definition returns the definition in the form the computed package compiles
+func (d FieldDefinition) Definition() computed.Definition {
+	return computed.Definition{
+		Entity:  d.Entity,
+		Name:    d.Name,
+		Label:   d.Label,
+		Kind:    d.Kind,
+		Formula: d.Formula,
+		Rollup:  d.Rollup,
+	}
+}
+
+// FieldDefinitionRequest creates or replaces a computed field
+type FieldDefinitionRequest struct {
+	Name    string           `json:"name"`
+	Label   string           `json:"label"`
+	Kind    computed.Kind    `json:"kind"`
+	Formula string           `json:"formula,omitempty"`
+	Rollup  *computed.Rollup `json:"rollup,omitempty"`
+}
+
+// RecordValues holds the computed field values of one record
+type RecordValues struct {
+	ID     uuid.UUID              `json:"id"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// SummaryRequest aggregates a computed field across an entity's records for reports
+type SummaryRequest struct {
+	Entity    string
+	Field     string
+	Aggregate computed.Aggregate
+	// GroupBy is optional; when empty a single row covers all records
+	GroupBy string
+}
+
+// SummaryRow is one group of a computed field summary
+type SummaryRow struct {
+	Group *string  `json:"group"`
+	Value *float64 `json:"value"`
+	Count int      `json:"count"`
+}
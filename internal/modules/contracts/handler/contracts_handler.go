@@ -0,0 +1,442 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ContractsHandler handles contracts, their documents and reminders, the
+// renewal forecast and the contract settings
+type ContractsHandler struct {
+	service *service.ContractsService
+}
+
+func NewContractsHandler(service *service.ContractsService) *ContractsHandler {
+	return &ContractsHandler{service: service}
+}
+
+func (h *ContractsHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/contracts", h.ListContracts)
+	router.POST("/api/v1/contracts", h.CreateContract)
+	router.GET("/api/v1/contracts/:id", h.GetContract)
+	router.PUT("/api/v1/contracts/:id", h.UpdateContract)
+	router.DELETE("/api/v1/contracts/:id", h.DeleteContract)
+	router.POST("/api/v1/contracts/:id/renew", h.RenewContract)
+	router.POST("/api/v1/contracts/:id/cancel", h.CancelContract)
+	router.GET("/api/v1/contracts/:id/documents", h.ListDocuments)
+	router.POST("/api/v1/contracts/:id/documents", h.AddDocument)
+	router.DELETE("/api/v1/contracts/:id/documents/:document_id", h.RemoveDocument)
+	router.GET("/api/v1/contracts/:id/reminders", h.ListReminders)
+
+	router.GET("/api/v1/contract-renewals/forecast", h.Forecast)
+	router.GET("/api/v1/contract-settings", h.GetSettings)
+	router.PUT("/api/v1/contract-settings", h.SaveSettings)
+}
+
+// ListContracts handles GET /api/v1/contracts with optional status,
+// contact_id, owner_id and ending_before (YYYY-MM-DD) filters
+func (h *ContractsHandler) ListContracts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.ContractFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.ContractStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	for name, dest := range map[string]**uuid.UUID{"contact_id": &filter.ContactID, "owner_id": &filter.OwnerID} {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = &id
+		}
+	}
+	if v := q.Get("ending_before"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid ending_before date", http.StatusBadRequest)
+			return
+		}
+		filter.EndingBefore = &t
+	}
+
+	contracts, err := h.service.ListContracts(r.Context(), filter)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contracts)
+}
+
+// CreateContract handles POST /api/v1/contracts
+func (h *ContractsHandler) CreateContract(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ContractRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contract, err := h.service.CreateContract(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, contract)
+}
+
+// GetContract handles GET /api/v1/contracts/:id
+func (h *ContractsHandler) GetContract(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	contract, err := h.service.GetContract(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contract)
+}
+
+// UpdateContract handles PUT /api/v1/contracts/:id
+func (h *ContractsHandler) UpdateContract(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ContractRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contract, err := h.service.UpdateContract(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contract)
+}
+
+// DeleteContract handles DELETE /api/v1/contracts/:id
+func (h *ContractsHandler) DeleteContract(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteContract(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RenewContract handles POST /api/v1/contracts/:id/renew, returning the
+// next term
+func (h *ContractsHandler) RenewContract(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RenewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	contract, err := h.service.RenewContract(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, contract)
+}
+
+// CancelContract handles POST /api/v1/contracts/:id/cancel
+func (h *ContractsHandler) CancelContract(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	contract, err := h.service.CancelContract(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contract)
+}
+
+// ListDocuments handles GET /api/v1/contracts/:id/documents
+func (h *ContractsHandler) ListDocuments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	documents, err := h.service.ListDocuments(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, documents)
+}
+
+// AddDocument handles POST /api/v1/contracts/:id/documents
+func (h *ContractsHandler) AddDocument(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	document, err := h.service.AddDocument(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, document)
+}
+
+// RemoveDocument handles DELETE /api/v1/contracts/:id/documents/:document_id
+func (h *ContractsHandler) RemoveDocument(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+	documentID, err := uuid.Parse(ps.ByName("document_id"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveDocument(r.Context(), authCtx.OrganizationID, id, documentID); err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListReminders handles GET /api/v1/contracts/:id/reminders
+func (h *ContractsHandler) ListReminders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return
+	}
+
+	reminders, err := h.service.ListReminders(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reminders)
+}
+
+// Forecast handles GET /api/v1/contract-renewals/forecast with optional
+// from and to months (YYYY-MM), or a fiscal period such as FY2025-Q2 to
+// forecast by fiscal period
+func (h *ContractsHandler) Forecast(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	if period := q.Get("period"); period != "" {
+		if q.Get("from") != "" || q.Get("to") != "" {
+			http.Error(w, "Use either period or from and to", http.StatusBadRequest)
+			return
+		}
+		forecast, err := h.service.ForecastPeriod(r.Context(), authCtx.OrganizationID, period)
+		if err != nil {
+			writeContractsError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, forecast)
+		return
+	}
+
+	var from, to time.Time
+	for name, dest := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse("2006-01", v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" month", http.StatusBadRequest)
+				return
+			}
+			*dest = t
+		}
+	}
+
+	forecast, err := h.service.Forecast(r.Context(), authCtx.OrganizationID, from, to)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, forecast)
+}
+
+// GetSettings handles GET /api/v1/contract-settings
+func (h *ContractsHandler) GetSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// SaveSettings handles PUT /api/v1/contract-settings
+func (h *ContractsHandler) SaveSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.SaveSettings(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeContractsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeContractsError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrClosed), errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
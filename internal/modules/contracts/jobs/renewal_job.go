@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeContractRenewals = "contracts.renewals"
+
+// RenewalJobHandler handles queued passes over the contracts due for
+// renewal, expiry or a reminder
+type RenewalJobHandler struct {
+	contractsService *service.ContractsService
+	logger           *slog.Logger
+}
+
+func NewRenewalJobHandler(contractsService *service.ContractsService, logger *slog.Logger) *RenewalJobHandler {
+	return &RenewalJobHandler{
+		contractsService: contractsService,
+		logger:           logger,
+	}
+}
+
+// Handle runs a renewal pass
+func (h *RenewalJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	result, err := h.contractsService.RunRenewals(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run contract renewals: %w", err)
+	}
+	if result.LeadsCreated+result.AutoRenewed+result.Expired+result.RemindersSent > 0 {
+		h.logger.Info("Contract renewals run",
+			"leads_created", result.LeadsCreated,
+			"auto_renewed", result.AutoRenewed,
+			"expired", result.Expired,
+			"reminders_sent", result.RemindersSent)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *RenewalJobHandler) JobType() string {
+	return JobTypeContractRenewals
+}
+
+// Scheduler runs renewal passes on a fixed interval
+type Scheduler struct {
+	handler     *RenewalJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *RenewalJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs a renewal pass every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeContractRenewals, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeContractRenewals, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled contract renewals failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule contract renewals", "error", err)
+	}
+}
@@ -0,0 +1,130 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// eventLeadWon renews the contract of a won renewal opportunity
+const eventLeadWon = "crm.lead.won"
+
+// ContractsModule represents the contracts and renewals module
+type ContractsModule struct {
+	contractsService *service.ContractsService
+	contractsHandler *handler.ContractsHandler
+	scheduler        *jobs.Scheduler
+	logger           *slog.Logger
+}
+
+// NewContractsModule creates a new contracts module
+func NewContractsModule() *ContractsModule {
+	return &ContractsModule{}
+}
+
+// Name returns the module name
+func (m *ContractsModule) Name() string {
+	return "contracts"
+}
+
+// SetMailer emails contract expiry reminders. It must be called after Init.
+func (m *ContractsModule) SetMailer(mailer service.Mailer) {
+	if m.contractsService != nil {
+		m.contractsService.SetMailer(mailer)
+	}
+}
+
+// Init initializes the contracts module
+func (m *ContractsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "contracts")
+	m.logger.Info("Initializing contracts module")
+
+	// Create repositories
+	contractsRepo := repository.NewContractsRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.contractsService = service.NewContractsService(contractsRepo, authAdapter, deps.EventBus, m.logger)
+	m.contractsService.SetFiscalPeriods(fiscal.NewPeriods(fiscal.NewStore(deps.DB)))
+
+	// Create scheduled renewals
+	renewalJobHandler := jobs.NewRenewalJobHandler(m.contractsService, m.logger)
+	m.scheduler = jobs.NewScheduler(renewalJobHandler, service.RenewalInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.contractsHandler = handler.NewContractsHandler(m.contractsService)
+
+	m.logger.Info("Contracts module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers contracts module routes
+func (m *ContractsModule) RegisterRoutes(router interface{}) {
+	if m.contractsHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.contractsHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers renews contracts when their renewal opportunity is
+// won
+func (m *ContractsModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.contractsService == nil {
+		return
+	}
+
+	eventBus.Subscribe(eventLeadWon, m.handleLeadWon)
+
+	m.logger.Info("Contracts module event handlers registered")
+}
+
+// handleLeadWon renews the contract a won lead was the renewal opportunity
+// of. Failures are logged rather than returned so they never fail winning
+// the lead.
+func (m *ContractsModule) handleLeadWon(ctx context.Context, event events.Event) error {
+	// The payload might be the struct itself or a map, depending on how it was published
+	var payload struct {
+		OrganizationID uuid.UUID `json:"organization_id"`
+		LeadID         uuid.UUID `json:"lead_id"`
+	}
+	bytes, err := json.Marshal(event.Payload)
+	if err == nil {
+		err = json.Unmarshal(bytes, &payload)
+	}
+	if err != nil {
+		m.logger.Error("Failed to decode lead won event", "error", err)
+		return nil
+	}
+	if payload.OrganizationID == uuid.Nil || payload.LeadID == uuid.Nil {
+		return nil
+	}
+
+	renewed, err := m.contractsService.HandleRenewalWon(ctx, payload.OrganizationID, payload.LeadID)
+	switch {
+	case err != nil:
+		m.logger.Error("Failed to renew contract of won opportunity", "lead_id", payload.LeadID, "error", err)
+	case renewed != nil:
+		m.logger.Info("Contract renewed by won opportunity", "lead_id", payload.LeadID, "contract_id", renewed.ID)
+	}
+	return nil
+}
+
+// Health checks the health of the contracts module
+func (m *ContractsModule) Health() error {
+	return nil
+}
@@ -0,0 +1,738 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a contract, contract document or linked
+	// record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a document is already linked to a
+	// contract
+	ErrDuplicate = errors.New("already exists")
+	// ErrClosed is returned when changing a contract term that was already
+	// renewed, expired or cancelled
+	ErrClosed = errors.New("contract is closed")
+	// ErrRenewalExists is returned when creating the renewal opportunity of
+	// a contract that already has one
+	ErrRenewalExists = errors.New("contract already has a renewal opportunity")
+)
+
+// ContractsRepo defines the interface for contracts repository operations
+type ContractsRepo interface {
+	GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error)
+	SaveSettings(ctx context.Context, orgID, userID uuid.UUID, request types.SettingsRequest) (*types.Settings, error)
+	ListContracts(ctx context.Context, filter types.ContractFilter) ([]types.Contract, error)
+	FindContract(ctx context.Context, orgID, id uuid.UUID) (*types.Contract, error)
+	CreateContract(ctx context.Context, orgID, userID uuid.UUID, request types.ContractRequest) (*types.Contract, error)
+	UpdateContract(ctx context.Context, orgID, userID, id uuid.UUID, request types.ContractRequest) (*types.Contract, error)
+	DeleteContract(ctx context.Context, orgID, id uuid.UUID) error
+	CancelContract(ctx context.Context, orgID, userID, id uuid.UUID, at time.Time) (*types.Contract, error)
+	RenewContract(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, next types.ContractRequest, at time.Time) (*types.Contract, error)
+	ExpireContract(ctx context.Context, orgID, id uuid.UUID, at time.Time) (*types.Contract, error)
+	ListDocuments(ctx context.Context, orgID, contractID uuid.UUID) ([]types.ContractDocument, error)
+	AddDocument(ctx context.Context, orgID, userID, contractID uuid.UUID, request types.DocumentRequest) (*types.ContractDocument, error)
+	RemoveDocument(ctx context.Context, orgID, contractID, id uuid.UUID) error
+	ListReminders(ctx context.Context, orgID, contractID uuid.UUID) ([]types.Reminder, error)
+	RecordReminder(ctx context.Context, orgID uuid.UUID, reminder types.Reminder) error
+	DueContracts(ctx context.Context, today time.Time, limit int) ([]types.DueContract, error)
+	CreateRenewalLead(ctx context.Context, orgID, contractID uuid.UUID, lead types.RenewalLead, at time.Time) (uuid.UUID, error)
+	ContractForRenewalLead(ctx context.Context, orgID, leadID uuid.UUID) (*types.Contract, *float64, error)
+	ForecastRows(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.ForecastRow, error)
+}
+
+// ContractsRepository stores contracts, their documents and reminders, and
+// creates their renewal opportunities
+type ContractsRepository struct {
+	db *sql.DB
+}
+
+// Ensure ContractsRepository implements ContractsRepo interface
+var _ ContractsRepo = &ContractsRepository{}
+
+func NewContractsRepository(db *sql.DB) *ContractsRepository {
+	return &ContractsRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func scanInts(values []int64) []int {
+	ints := make([]int, len(values))
+	for i, v := range values {
+		ints[i] = int(v)
+	}
+	return ints
+}
+
+func (r *ContractsRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	settings := types.DefaultSettings(orgID)
+	var reminderDays []int64
+	var updatedAt sql.NullTime
+	var updatedBy uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT renewal_lead_days, reminder_days, notify_customer, updated_at, updated_by
+		FROM contract_settings WHERE organization_id = $1
+	`, orgID).Scan(&settings.RenewalLeadDays, pq.Array(&reminderDays), &settings.NotifyCustomer, &updatedAt, &updatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract settings: %w", err)
+	}
+	settings.ReminderDays = scanInts(reminderDays)
+	settings.UpdatedAt = nullTime(updatedAt)
+	settings.UpdatedBy = nullUUID(updatedBy)
+	return &settings, nil
+}
+
+func (r *ContractsRepository) SaveSettings(ctx context.Context, orgID, userID uuid.UUID, request types.SettingsRequest) (*types.Settings, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO contract_settings (organization_id, renewal_lead_days, reminder_days, notify_customer, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			renewal_lead_days = EXCLUDED.renewal_lead_days,
+			reminder_days = EXCLUDED.reminder_days,
+			notify_customer = EXCLUDED.notify_customer,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = now()
+	`, orgID, request.RenewalLeadDays, pq.Array(request.ReminderDays), request.NotifyCustomer, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save contract settings: %w", err)
+	}
+	return r.GetSettings(ctx, orgID)
+}
+
+const contractColumns = `
+	c.id, c.organization_id, c.name, c.reference, c.contact_id, c.owner_id, c.status,
+	c.start_date, c.end_date, c.value, c.auto_renew, c.renewal_term_months, c.renewal_lead_days,
+	c.notes, c.renewal_lead_id, c.renewed_from_id, c.closed_at,
+	c.created_at, c.updated_at, c.created_by, c.updated_by
+`
+
+func scanContract(row rowScanner, extra ...interface{}) (*types.Contract, error) {
+	var c types.Contract
+	var contactID, ownerID, renewalLeadID, renewedFromID, createdBy, updatedBy uuid.NullUUID
+	var renewalLeadDays sql.NullInt64
+	var closedAt sql.NullTime
+	dest := []interface{}{
+		&c.ID, &c.OrganizationID, &c.Name, &c.Reference, &contactID, &ownerID, &c.Status,
+		&c.StartDate, &c.EndDate, &c.Value, &c.AutoRenew, &c.RenewalTermMonths, &renewalLeadDays,
+		&c.Notes, &renewalLeadID, &renewedFromID, &closedAt,
+		&c.CreatedAt, &c.UpdatedAt, &createdBy, &updatedBy,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	c.ContactID = nullUUID(contactID)
+	c.OwnerID = nullUUID(ownerID)
+	c.RenewalLeadDays = nullInt(renewalLeadDays)
+	c.RenewalLeadID = nullUUID(renewalLeadID)
+	c.RenewedFromID = nullUUID(renewedFromID)
+	c.ClosedAt = nullTime(closedAt)
+	c.CreatedBy = nullUUID(createdBy)
+	c.UpdatedBy = nullUUID(updatedBy)
+	return &c, nil
+}
+
+func (r *ContractsRepository) ListContracts(ctx context.Context, filter types.ContractFilter) ([]types.Contract, error) {
+	where := []string{"c.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("c.status = $%d", len(args)))
+	}
+	if filter.ContactID != nil {
+		args = append(args, *filter.ContactID)
+		where = append(where, fmt.Sprintf("c.contact_id = $%d", len(args)))
+	}
+	if filter.OwnerID != nil {
+		args = append(args, *filter.OwnerID)
+		where = append(where, fmt.Sprintf("c.owner_id = $%d", len(args)))
+	}
+	if filter.EndingBefore != nil {
+		args = append(args, *filter.EndingBefore)
+		where = append(where, fmt.Sprintf("c.end_date <= $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+contractColumns+` FROM contracts c
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY c.end_date, c.name, c.id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contracts := []types.Contract{}
+	for rows.Next() {
+		c, err := scanContract(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contracts = append(contracts, *c)
+	}
+	return contracts, rows.Err()
+}
+
+func (r *ContractsRepository) FindContract(ctx context.Context, orgID, id uuid.UUID) (*types.Contract, error) {
+	c, err := scanContract(r.db.QueryRowContext(ctx, `
+		SELECT `+contractColumns+` FROM contracts c WHERE c.id = $1 AND c.organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contract %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contract: %w", err)
+	}
+	return c, nil
+}
+
+func (r *ContractsRepository) CreateContract(ctx context.Context, orgID, userID uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO contracts (
+			organization_id, name, reference, contact_id, owner_id, status, start_date, end_date, value,
+			auto_renew, renewal_term_months, renewal_lead_days, notes, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+		RETURNING id
+	`, orgID, request.Name, request.Reference, request.ContactID, request.OwnerID, request.Status,
+		request.StartDate, request.EndDate, request.Value, request.AutoRenew, request.RenewalTermMonths,
+		request.RenewalLeadDays, request.Notes, userID).Scan(&id)
+	if isForeignKeyViolation(err) {
+		return nil, fmt.Errorf("contact %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract: %w", err)
+	}
+	return r.FindContract(ctx, orgID, id)
+}
+
+// UpdateContract updates a draft or active contract. Moving its end date
+// restarts its expiry reminders.
+func (r *ContractsRepository) UpdateContract(ctx context.Context, orgID, userID, id uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lockOpen(ctx, tx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE contracts SET
+			name = $3, reference = $4, contact_id = $5, owner_id = $6, status = $7, start_date = $8,
+			end_date = $9, value = $10, auto_renew = $11, renewal_term_months = $12, renewal_lead_days = $13,
+			notes = $14, updated_by = $15, updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID, request.Name, request.Reference, request.ContactID, request.OwnerID, request.Status,
+		request.StartDate, request.EndDate, request.Value, request.AutoRenew, request.RenewalTermMonths,
+		request.RenewalLeadDays, request.Notes, userID)
+	if isForeignKeyViolation(err) {
+		return nil, fmt.Errorf("contact %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update contract: %w", err)
+	}
+
+	if !current.EndDate.Equal(request.EndDate) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM contract_reminders WHERE contract_id = $1`, id); err != nil {
+			return nil, fmt.Errorf("failed to reset contract reminders: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.FindContract(ctx, orgID, id)
+}
+
+// DeleteContract removes a contract with its documents and reminders. Its
+// renewal opportunity and next term stay.
+func (r *ContractsRepository) DeleteContract(ctx context.Context, orgID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM contracts WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete contract: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("contract %w", ErrNotFound)
+	}
+	return nil
+}
+
+// CancelContract cancels a draft or active contract; it is neither renewed
+// nor reminded of anymore
+func (r *ContractsRepository) CancelContract(ctx context.Context, orgID, userID, id uuid.UUID, at time.Time) (*types.Contract, error) {
+	return r.close(ctx, orgID, id, &userID, types.ContractCancelled, at)
+}
+
+// ExpireContract closes an active contract that ended without being renewed
+func (r *ContractsRepository) ExpireContract(ctx context.Context, orgID, id uuid.UUID, at time.Time) (*types.Contract, error) {
+	return r.close(ctx, orgID, id, nil, types.ContractExpired, at)
+}
+
+func (r *ContractsRepository) close(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, status types.ContractStatus, at time.Time) (*types.Contract, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpen(ctx, tx, orgID, id); err != nil {
+		return nil, err
+	}
+
+	closed, err := scanContract(tx.QueryRowContext(ctx, `
+		UPDATE contracts c SET status = $3, closed_at = $4, updated_by = COALESCE($5, c.updated_by), updated_at = now()
+		WHERE c.id = $1 AND c.organization_id = $2
+		RETURNING `+contractColumns,
+		id, orgID, status, at, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to close contract: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return closed, nil
+}
+
+// RenewContract creates the next term of an active or expired contract and
+// marks the contract renewed, in one transaction. The next term keeps the
+// contract's renewal opportunity out of its own pipeline.
+func (r *ContractsRepository) RenewContract(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, next types.ContractRequest, at time.Time) (*types.Contract, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status types.ContractStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM contracts WHERE id = $1 AND organization_id = $2 FOR UPDATE
+	`, id, orgID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contract %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock contract: %w", err)
+	}
+	if status != types.ContractActive && status != types.ContractExpired {
+		return nil, fmt.Errorf("%w: it is %s", ErrClosed, status)
+	}
+
+	var nextID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO contracts (
+			organization_id, name, reference, contact_id, owner_id, status, start_date, end_date, value,
+			auto_renew, renewal_term_months, renewal_lead_days, notes, renewed_from_id, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, 'active', $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+		RETURNING id
+	`, orgID, next.Name, next.Reference, next.ContactID, next.OwnerID, next.StartDate, next.EndDate,
+		next.Value, next.AutoRenew, next.RenewalTermMonths, next.RenewalLeadDays, next.Notes, id, userID).Scan(&nextID)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: it was already renewed", ErrClosed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create next contract term: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE contracts c SET status = 'renewed', closed_at = $3, updated_by = COALESCE($4, c.updated_by), updated_at = now()
+		WHERE c.id = $1 AND c.organization_id = $2
+	`, id, orgID, at, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark contract renewed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.FindContract(ctx, orgID, nextID)
+}
+
+// lockOpen locks a draft or active contract for update
+func lockOpen(ctx context.Context, tx *sql.Tx, orgID, id uuid.UUID) (*types.Contract, error) {
+	c, err := scanContract(tx.QueryRowContext(ctx, `
+		SELECT `+contractColumns+` FROM contracts c WHERE c.id = $1 AND c.organization_id = $2 FOR UPDATE
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contract %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock contract: %w", err)
+	}
+	if c.Status.IsClosed() {
+		return nil, fmt.Errorf("%w: it is %s", ErrClosed, c.Status)
+	}
+	return c, nil
+}
+
+const documentColumns = `
+	d.id, d.contract_id, d.attachment_id, d.generated_document_id, d.label,
+	COALESCE(a.file_name, g.filename, ''), d.created_by, d.created_at
+`
+
+const documentJoins = `
+	LEFT JOIN attachments a ON a.id = d.attachment_id
+	LEFT JOIN generated_documents g ON g.id = d.generated_document_id
+`
+
+func scanDocument(row rowScanner) (*types.ContractDocument, error) {
+	var d types.ContractDocument
+	var attachmentID, generatedID, createdBy uuid.NullUUID
+	err := row.Scan(&d.ID, &d.ContractID, &attachmentID, &generatedID, &d.Label, &d.FileName, &createdBy, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.AttachmentID = nullUUID(attachmentID)
+	d.GeneratedDocumentID = nullUUID(generatedID)
+	d.CreatedBy = nullUUID(createdBy)
+	return &d, nil
+}
+
+func (r *ContractsRepository) ListDocuments(ctx context.Context, orgID, contractID uuid.UUID) ([]types.ContractDocument, error) {
+	if _, err := r.FindContract(ctx, orgID, contractID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+documentColumns+` FROM contract_documents d `+documentJoins+`
+		WHERE d.contract_id = $1 AND d.organization_id = $2
+		ORDER BY d.created_at, d.id
+	`, contractID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contract documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []types.ContractDocument{}
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contract document: %w", err)
+		}
+		documents = append(documents, *d)
+	}
+	return documents, rows.Err()
+}
+
+// AddDocument links an attachment or generated document of the
+// organization to a contract
+func (r *ContractsRepository) AddDocument(ctx context.Context, orgID, userID, contractID uuid.UUID, request types.DocumentRequest) (*types.ContractDocument, error) {
+	if _, err := r.FindContract(ctx, orgID, contractID); err != nil {
+		return nil, err
+	}
+
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO contract_documents (organization_id, contract_id, attachment_id, generated_document_id, label, created_by)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE ($3::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM attachments WHERE id = $3 AND organization_id = $1
+		)) AND ($4::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM generated_documents WHERE id = $4 AND organization_id = $1
+		))
+		RETURNING id
+	`, orgID, contractID, request.AttachmentID, request.GeneratedDocumentID, request.Label, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("document %w", ErrNotFound)
+	}
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("contract document %w", ErrDuplicate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add contract document: %w", err)
+	}
+
+	d, err := scanDocument(r.db.QueryRowContext(ctx, `
+		SELECT `+documentColumns+` FROM contract_documents d `+documentJoins+` WHERE d.id = $1
+	`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contract document: %w", err)
+	}
+	return d, nil
+}
+
+func (r *ContractsRepository) RemoveDocument(ctx context.Context, orgID, contractID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM contract_documents WHERE id = $1 AND contract_id = $2 AND organization_id = $3
+	`, id, contractID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to remove contract document: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("contract document %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (r *ContractsRepository) ListReminders(ctx context.Context, orgID, contractID uuid.UUID) ([]types.Reminder, error) {
+	if _, err := r.FindContract(ctx, orgID, contractID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT contract_id, days_before, status, recipients, error, created_at
+		FROM contract_reminders
+		WHERE contract_id = $1 AND organization_id = $2
+		ORDER BY days_before DESC
+	`, contractID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contract reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []types.Reminder{}
+	for rows.Next() {
+		var rem types.Reminder
+		var recipients []string
+		if err := rows.Scan(&rem.ContractID, &rem.DaysBefore, &rem.Status, pq.Array(&recipients), &rem.Error, &rem.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contract reminder: %w", err)
+		}
+		rem.Recipients = recipients
+		if rem.Recipients == nil {
+			rem.Recipients = []string{}
+		}
+		reminders = append(reminders, rem)
+	}
+	return reminders, rows.Err()
+}
+
+// RecordReminder records the outcome of a reminder step. A step already
+// recorded keeps its first outcome.
+func (r *ContractsRepository) RecordReminder(ctx context.Context, orgID uuid.UUID, reminder types.Reminder) error {
+	recipients := reminder.Recipients
+	if recipients == nil {
+		recipients = []string{}
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO contract_reminders (contract_id, organization_id, days_before, status, recipients, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (contract_id, days_before) DO NOTHING
+	`, reminder.ContractID, orgID, reminder.DaysBefore, reminder.Status, pq.Array(recipients), reminder.Error, reminder.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record contract reminder: %w", err)
+	}
+	return nil
+}
+
+// DueContracts returns the active contracts of all organizations that have
+// ended, whose renewal opportunity is due or that have a reminder step due
+// on today, soonest ending first
+func (r *ContractsRepository) DueContracts(ctx context.Context, today time.Time, limit int) ([]types.DueContract, error) {
+	defaults := types.DefaultSettings(uuid.Nil)
+	rows, err := r.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT c.id,
+				COALESCE(s.renewal_lead_days, $2) AS renewal_lead_days,
+				COALESCE(s.reminder_days, $3::integer[]) AS reminder_days,
+				COALESCE(s.notify_customer, false) AS notify_customer
+			FROM contracts c
+			LEFT JOIN contract_settings s ON s.organization_id = c.organization_id
+			WHERE c.status = 'active'
+		)
+		SELECT `+contractColumns+`,
+			due.renewal_lead_days, due.reminder_days, due.notify_customer,
+			COALESCE(u.email, ''), COALESCE(ct.email, ''),
+			ARRAY(SELECT r.days_before FROM contract_reminders r WHERE r.contract_id = c.id)
+		FROM due
+		JOIN contracts c ON c.id = due.id
+		LEFT JOIN users u ON u.id = c.owner_id
+		LEFT JOIN contacts ct ON ct.id = c.contact_id
+		WHERE c.end_date < $1::date
+			OR (NOT c.auto_renew AND c.renewal_lead_id IS NULL
+				AND c.end_date - COALESCE(c.renewal_lead_days, due.renewal_lead_days) <= $1::date)
+			OR EXISTS (
+				SELECT 1 FROM unnest(due.reminder_days) d
+				WHERE c.end_date - d <= $1::date
+					AND NOT EXISTS (SELECT 1 FROM contract_reminders r WHERE r.contract_id = c.id AND r.days_before = d)
+			)
+		ORDER BY c.end_date, c.id
+		LIMIT $4
+	`, today, defaults.RenewalLeadDays, pq.Array(defaults.ReminderDays), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due contracts: %w", err)
+	}
+	defer rows.Close()
+
+	due := []types.DueContract{}
+	for rows.Next() {
+		var d types.DueContract
+		var reminderDays, remindedDays []int64
+		c, err := scanContract(rows,
+			&d.Settings.RenewalLeadDays, pq.Array(&reminderDays), &d.Settings.NotifyCustomer,
+			&d.OwnerEmail, &d.CustomerEmail, pq.Array(&remindedDays))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan due contract: %w", err)
+		}
+		d.Contract = *c
+		d.Settings.OrganizationID = c.OrganizationID
+		d.Settings.ReminderDays = scanInts(reminderDays)
+		d.RemindedDays = scanInts(remindedDays)
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// CreateRenewalLead creates the renewal opportunity of a contract and links
+// it, in one transaction
+func (r *ContractsRepository) CreateRenewalLead(ctx context.Context, orgID, contractID uuid.UUID, lead types.RenewalLead, at time.Time) (uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var renewalLeadID uuid.NullUUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT renewal_lead_id FROM contracts WHERE id = $1 AND organization_id = $2 FOR UPDATE
+	`, contractID, orgID).Scan(&renewalLeadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("contract %w", ErrNotFound)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to lock contract: %w", err)
+	}
+	if renewalLeadID.Valid {
+		return uuid.Nil, ErrRenewalExists
+	}
+
+	var leadID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO leads (
+			organization_id, name, contact_id, assigned_to, lead_type, expected_revenue, date_deadline,
+			description, date_open
+		) VALUES ($1, $2, $3, $4, 'opportunity', $5, $6, $7, $8)
+		RETURNING id
+	`, orgID, lead.Name, lead.ContactID, lead.AssignedTo, lead.ExpectedRevenue, lead.Deadline,
+		lead.Description, at).Scan(&leadID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create renewal opportunity: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE contracts SET renewal_lead_id = $3, updated_at = now() WHERE id = $1 AND organization_id = $2
+	`, contractID, orgID, leadID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to link renewal opportunity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return leadID, nil
+}
+
+// ContractForRenewalLead returns the contract a renewal opportunity renews
+// with the opportunity's expected revenue
+func (r *ContractsRepository) ContractForRenewalLead(ctx context.Context, orgID, leadID uuid.UUID) (*types.Contract, *float64, error) {
+	var revenue sql.NullFloat64
+	c, err := scanContract(r.db.QueryRowContext(ctx, `
+		SELECT `+contractColumns+`, l.expected_revenue
+		FROM contracts c
+		JOIN leads l ON l.id = c.renewal_lead_id
+		WHERE c.renewal_lead_id = $1 AND c.organization_id = $2
+	`, leadID, orgID), &revenue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("contract %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find contract of renewal opportunity: %w", err)
+	}
+	return c, nullFloat(revenue), nil
+}
+
+// ForecastRows returns the active, renewed and expired contracts ending
+// between from and to, both included, with their renewal opportunity
+func (r *ContractsRepository) ForecastRows(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.ForecastRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.end_date, c.status, c.value, c.auto_renew,
+			l.id IS NOT NULL AND l.deleted_at IS NULL,
+			COALESCE(CASE WHEN l.won_status IN ('won', 'lost') THEN l.won_status END, ''),
+			COALESCE(l.probability, 0), l.expected_revenue
+		FROM contracts c
+		LEFT JOIN leads l ON l.id = c.renewal_lead_id
+		WHERE c.organization_id = $1
+			AND c.status IN ('active', 'renewed', 'expired')
+			AND c.end_date BETWEEN $2::date AND $3::date
+		ORDER BY c.end_date, c.id
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forecast contracts: %w", err)
+	}
+	defer rows.Close()
+
+	forecast := []types.ForecastRow{}
+	for rows.Next() {
+		var row types.ForecastRow
+		var revenue sql.NullFloat64
+		err := rows.Scan(&row.ContractID, &row.EndDate, &row.Status, &row.Value, &row.AutoRenew,
+			&row.HasLead, &row.LeadStatus, &row.LeadProbability, &revenue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan forecast contract: %w", err)
+		}
+		row.LeadRevenue = nullFloat(revenue)
+		forecast = append(forecast, row)
+	}
+	return forecast, rows.Err()
+}
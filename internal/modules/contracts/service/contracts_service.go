@@ -0,0 +1,704 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RenewalInterval is how often contracts are checked for renewal
+	// opportunities, expiry and reminders
+	RenewalInterval = time.Hour
+	// DefaultPageSize is the page size of contract lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of contract lists
+	MaxPageSize = 500
+	// DefaultForecastMonths is the length of a forecast without an end
+	DefaultForecastMonths = 12
+	// MaxForecastMonths bounds the length of a forecast
+	MaxForecastMonths = 36
+	// MaxReminderSteps bounds the steps of the expiry reminder sequence
+	MaxReminderSteps = 10
+	// renewalBatch bounds the contracts handled by one renewal pass
+	renewalBatch = 200
+	// sendTimeout bounds the delivery of one reminder email
+	sendTimeout = 30 * time.Second
+)
+
+// Events published as contracts move through their renewal
+const (
+	EventRenewalOpportunityCreated = "contracts.renewal_opportunity_created"
+	EventContractRenewed           = "contracts.renewed"
+	EventContractExpired           = "contracts.expired"
+	EventContractCancelled         = "contracts.cancelled"
+)
+
+// ErrInvalid wraps validation failures of contracts, settings and filters
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the contracts service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer delivers expiry reminders by email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// FiscalPeriods resolves fiscal period parameters in the organization's
+// fiscal calendar
+type FiscalPeriods interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error)
+	Buckets(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]fiscal.Period, error)
+}
+
+// ContractsService manages customer contracts and their renewal: it opens
+// renewal opportunities ahead of expiry, renews auto-renewing terms,
+// expires the others and emails expiry reminders
+type ContractsService struct {
+	repo        repository.ContractsRepo
+	authService AuthService
+	eventBus    *events.Bus
+	mailer      Mailer
+	periods     FiscalPeriods
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewContractsService(repo repository.ContractsRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *ContractsService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ContractsService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails expiry reminders. Without a mailer the reminder steps
+// are recorded as skipped.
+func (s *ContractsService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetFiscalPeriods enables forecasts over fiscal periods
+func (s *ContractsService) SetFiscalPeriods(periods FiscalPeriods) {
+	s.periods = periods
+}
+
+// GetSettings returns the organization's renewal lead time and reminder
+// sequence
+func (s *ContractsService) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.GetSettings(ctx, orgID)
+}
+
+// SaveSettings replaces the organization's renewal lead time and reminder
+// sequence
+func (s *ContractsService) SaveSettings(ctx context.Context, orgID, userID uuid.UUID, request types.SettingsRequest) (*types.Settings, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.RenewalLeadDays < 0 || request.RenewalLeadDays > 365 {
+		return nil, fmt.Errorf("%w: renewal_lead_days must be between 0 and 365", ErrInvalid)
+	}
+	if len(request.ReminderDays) > MaxReminderSteps {
+		return nil, fmt.Errorf("%w: at most %d reminder steps", ErrInvalid, MaxReminderSteps)
+	}
+
+	seen := make(map[int]bool, len(request.ReminderDays))
+	days := make([]int, 0, len(request.ReminderDays))
+	for _, d := range request.ReminderDays {
+		if d < 0 || d > 365 {
+			return nil, fmt.Errorf("%w: reminder days must be between 0 and 365", ErrInvalid)
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(days)))
+	request.ReminderDays = days
+
+	return s.repo.SaveSettings(ctx, orgID, userID, request)
+}
+
+// ListContracts returns the organization's contracts, soonest ending first
+func (s *ContractsService) ListContracts(ctx context.Context, filter types.ContractFilter) ([]types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListContracts(ctx, filter)
+}
+
+// GetContract returns a contract
+func (s *ContractsService) GetContract(ctx context.Context, orgID, id uuid.UUID) (*types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindContract(ctx, orgID, id)
+}
+
+// CreateContract creates a draft or active contract
+func (s *ContractsService) CreateContract(ctx context.Context, orgID, userID uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateContract(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateContract(ctx, orgID, userID, request)
+}
+
+// UpdateContract updates a draft or active contract
+func (s *ContractsService) UpdateContract(ctx context.Context, orgID, userID, id uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateContract(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateContract(ctx, orgID, userID, id, request)
+}
+
+// DeleteContract removes a contract
+func (s *ContractsService) DeleteContract(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteContract(ctx, orgID, id)
+}
+
+// CancelContract cancels a draft or active contract
+func (s *ContractsService) CancelContract(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	contract, err := s.repo.CancelContract(ctx, orgID, userID, id, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, EventContractCancelled, contract, nil)
+	return contract, nil
+}
+
+// RenewContract renews an active or expired contract into its next term,
+// which is returned
+func (s *ContractsService) RenewContract(ctx context.Context, orgID, userID, id uuid.UUID, request types.RenewRequest) (*types.Contract, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	contract, err := s.repo.FindContract(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := NextTerm(contract)
+	if request.EndDate != nil {
+		next.EndDate = dateOf(*request.EndDate)
+		if next.EndDate.Before(next.StartDate) {
+			return nil, fmt.Errorf("%w: end_date is before the next term starts on %s", ErrInvalid, next.StartDate.Format("2006-01-02"))
+		}
+	}
+	if request.Value != nil {
+		if *request.Value < 0 {
+			return nil, fmt.Errorf("%w: value cannot be negative", ErrInvalid)
+		}
+		next.Value = *request.Value
+	}
+	return s.renew(ctx, contract, &userID, next)
+}
+
+// ListDocuments returns the documents filed with a contract
+func (s *ContractsService) ListDocuments(ctx context.Context, orgID, contractID uuid.UUID) ([]types.ContractDocument, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListDocuments(ctx, orgID, contractID)
+}
+
+// AddDocument files an attachment or a generated document with a contract
+func (s *ContractsService) AddDocument(ctx context.Context, orgID, userID, contractID uuid.UUID, request types.DocumentRequest) (*types.ContractDocument, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if (request.AttachmentID == nil) == (request.GeneratedDocumentID == nil) {
+		return nil, fmt.Errorf("%w: one of attachment_id and generated_document_id is required", ErrInvalid)
+	}
+	request.Label = strings.TrimSpace(request.Label)
+	return s.repo.AddDocument(ctx, orgID, userID, contractID, request)
+}
+
+// RemoveDocument unlinks a document from a contract
+func (s *ContractsService) RemoveDocument(ctx context.Context, orgID, contractID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "contracts:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.RemoveDocument(ctx, orgID, contractID, id)
+}
+
+// ListReminders returns the expiry reminder steps recorded for a contract
+func (s *ContractsService) ListReminders(ctx context.Context, orgID, contractID uuid.UUID) ([]types.Reminder, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListReminders(ctx, orgID, contractID)
+}
+
+// Forecast returns the renewal forecast of the contracts ending in the
+// months from the month of from through the month of to. It defaults to
+// the next DefaultForecastMonths months.
+func (s *ContractsService) Forecast(ctx context.Context, orgID uuid.UUID, from, to time.Time) (*types.Forecast, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	if from.IsZero() {
+		from = s.now()
+	}
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if to.IsZero() {
+		to = from.AddDate(0, DefaultForecastMonths-1, 0)
+	}
+	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, -1)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalid)
+	}
+	if months := monthsBetween(from, to); months > MaxForecastMonths {
+		return nil, fmt.Errorf("%w: a forecast covers at most %d months", ErrInvalid, MaxForecastMonths)
+	}
+
+	rows, err := s.repo.ForecastRows(ctx, orgID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return BuildForecast(from, to, rows), nil
+}
+
+// ForecastPeriod returns the renewal forecast of the contracts ending in a
+// fiscal year, quarter or period, such as FY2025-Q2, by fiscal period
+func (s *ContractsService) ForecastPeriod(ctx context.Context, orgID uuid.UUID, spec string) (*types.Forecast, error) {
+	if err := s.authService.CheckPermission(ctx, "contracts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.periods == nil {
+		return nil, fmt.Errorf("%w: fiscal periods are not available", ErrInvalid)
+	}
+
+	period, err := s.periods.Resolve(ctx, orgID, spec)
+	if errors.Is(err, fiscal.ErrInvalidPeriod) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := s.periods.Buckets(ctx, orgID, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ForecastRows(ctx, orgID, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+	return BuildFiscalForecast(period, buckets, rows), nil
+}
+
+// HandleRenewalWon renews the contract a won renewal opportunity was
+// created for, at the opportunity's expected revenue. Opportunities that
+// renew no contract are ignored.
+func (s *ContractsService) HandleRenewalWon(ctx context.Context, orgID, leadID uuid.UUID) (*types.Contract, error) {
+	contract, revenue, err := s.repo.ContractForRenewalLead(ctx, orgID, leadID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if contract.Status != types.ContractActive && contract.Status != types.ContractExpired {
+		return nil, nil
+	}
+
+	next := NextTerm(contract)
+	if revenue != nil {
+		next.Value = *revenue
+	}
+	return s.renew(ctx, contract, nil, next)
+}
+
+// RunRenewals handles the active contracts due: the ended ones are renewed
+// when they auto-renew and expired otherwise, the renewal opportunities
+// due are created and the reminder steps due are emailed
+func (s *ContractsService) RunRenewals(ctx context.Context) (*types.RenewalResult, error) {
+	today := dateOf(s.now())
+	due, err := s.repo.DueContracts(ctx, today, renewalBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.RenewalResult{}
+	for i := range due {
+		contract := &due[i]
+
+		if contract.EndDate.Before(today) {
+			if contract.AutoRenew {
+				if _, err := s.renew(ctx, &contract.Contract, nil, NextTerm(&contract.Contract)); err != nil {
+					s.logger.Error("Failed to auto-renew contract", "contract_id", contract.ID, "error", err)
+					continue
+				}
+				result.AutoRenewed++
+				continue
+			}
+			expired, err := s.repo.ExpireContract(ctx, contract.OrganizationID, contract.ID, s.now())
+			if err != nil {
+				s.logger.Error("Failed to expire contract", "contract_id", contract.ID, "error", err)
+				continue
+			}
+			s.publish(ctx, EventContractExpired, expired, nil)
+			result.Expired++
+			continue
+		}
+
+		if RenewalLeadDue(contract, today) {
+			created, err := s.createRenewalLead(ctx, contract)
+			if err != nil {
+				s.logger.Error("Failed to create renewal opportunity", "contract_id", contract.ID, "error", err)
+			} else if created {
+				result.LeadsCreated++
+			}
+		}
+
+		if s.remind(ctx, contract, today) {
+			result.RemindersSent++
+		}
+	}
+	return result, nil
+}
+
+// RenewalLeadDue reports whether a contract that does not auto-renew needs
+// its renewal opportunity created on today
+func RenewalLeadDue(contract *types.DueContract, today time.Time) bool {
+	if contract.AutoRenew || contract.RenewalLeadID != nil {
+		return false
+	}
+	return !today.Before(contract.EndDate.AddDate(0, 0, -contract.LeadDays(contract.Settings)))
+}
+
+// DueReminderSteps returns the reminder steps of a contract that are due on
+// today and not recorded yet, closest to the end date last. Only that one
+// is emailed; the others were missed, for instance because the contract
+// was created late, and are skipped.
+func DueReminderSteps(contract *types.DueContract, today time.Time) []int {
+	reminded := make(map[int]bool, len(contract.RemindedDays))
+	for _, d := range contract.RemindedDays {
+		reminded[d] = true
+	}
+
+	var steps []int
+	for _, d := range contract.Settings.ReminderDays {
+		if reminded[d] || today.Before(contract.EndDate.AddDate(0, 0, -d)) || today.After(contract.EndDate) {
+			continue
+		}
+		steps = append(steps, d)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(steps)))
+	return steps
+}
+
+// NextTerm is the term following a contract: it starts the day after the
+// contract ends, runs for the renewal term and keeps the contract's terms
+func NextTerm(contract *types.Contract) types.ContractRequest {
+	start := contract.EndDate.AddDate(0, 0, 1)
+	return types.ContractRequest{
+		Name:              contract.Name,
+		Reference:         contract.Reference,
+		ContactID:         contract.ContactID,
+		OwnerID:           contract.OwnerID,
+		Status:            types.ContractActive,
+		StartDate:         start,
+		EndDate:           start.AddDate(0, contract.RenewalTermMonths, -1),
+		Value:             contract.Value,
+		AutoRenew:         contract.AutoRenew,
+		RenewalTermMonths: contract.RenewalTermMonths,
+		RenewalLeadDays:   contract.RenewalLeadDays,
+		Notes:             contract.Notes,
+	}
+}
+
+func (s *ContractsService) renew(ctx context.Context, contract *types.Contract, userID *uuid.UUID, next types.ContractRequest) (*types.Contract, error) {
+	renewed, err := s.repo.RenewContract(ctx, contract.OrganizationID, contract.ID, userID, next, s.now())
+	if err != nil {
+		return nil, err
+	}
+	closed := *contract
+	closed.Status = types.ContractRenewed
+	s.publish(ctx, EventContractRenewed, &closed, &renewed.ID)
+	return renewed, nil
+}
+
+func (s *ContractsService) createRenewalLead(ctx context.Context, contract *types.DueContract) (bool, error) {
+	lead := types.RenewalLead{
+		Name:            "Renewal: " + contract.Name,
+		ContactID:       contract.ContactID,
+		AssignedTo:      contract.OwnerID,
+		ExpectedRevenue: contract.Value,
+		Deadline:        contract.EndDate,
+		Description: fmt.Sprintf("Renewal of contract %s, which ends on %s.",
+			contractLabel(&contract.Contract), contract.EndDate.Format("2006-01-02")),
+	}
+	leadID, err := s.repo.CreateRenewalLead(ctx, contract.OrganizationID, contract.ID, lead, s.now())
+	if errors.Is(err, repository.ErrRenewalExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	contract.RenewalLeadID = &leadID
+	s.publish(ctx, EventRenewalOpportunityCreated, &contract.Contract, nil)
+	return true, nil
+}
+
+// remind emails the reminder step due for a contract, if any, and reports
+// whether it was sent
+func (s *ContractsService) remind(ctx context.Context, contract *types.DueContract, today time.Time) bool {
+	steps := DueReminderSteps(contract, today)
+	if len(steps) == 0 {
+		return false
+	}
+
+	for _, d := range steps[:len(steps)-1] {
+		s.record(ctx, contract, types.Reminder{DaysBefore: d, Status: types.ReminderSkipped, Error: "a later reminder step was due"})
+	}
+
+	reminder := types.Reminder{DaysBefore: steps[len(steps)-1], Status: types.ReminderSent}
+	if contract.OwnerEmail != "" {
+		reminder.Recipients = append(reminder.Recipients, contract.OwnerEmail)
+	}
+	if contract.Settings.NotifyCustomer && contract.CustomerEmail != "" {
+		reminder.Recipients = append(reminder.Recipients, contract.CustomerEmail)
+	}
+
+	switch {
+	case s.mailer == nil:
+		reminder.Status = types.ReminderSkipped
+		reminder.Error = "no mailer configured"
+	case len(reminder.Recipients) == 0:
+		reminder.Status = types.ReminderSkipped
+		reminder.Error = "no recipient email"
+	default:
+		subject, body := reminderMessage(&contract.Contract, today)
+		for _, to := range reminder.Recipients {
+			sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, contract.OrganizationID), sendTimeout)
+			err := s.mailer.Send(sendCtx, to, subject, body)
+			cancel()
+			if err != nil {
+				s.logger.Warn("Failed to email contract expiry reminder", "contract_id", contract.ID, "error", err)
+				reminder.Status = types.ReminderFailed
+				reminder.Error = err.Error()
+			}
+		}
+	}
+
+	s.record(ctx, contract, reminder)
+	return reminder.Status == types.ReminderSent
+}
+
+func (s *ContractsService) record(ctx context.Context, contract *types.DueContract, reminder types.Reminder) {
+	reminder.ContractID = contract.ID
+	reminder.CreatedAt = s.now()
+	if err := s.repo.RecordReminder(ctx, contract.OrganizationID, reminder); err != nil {
+		s.logger.Error("Failed to record contract reminder", "contract_id", contract.ID, "error", err)
+	}
+}
+
+func reminderMessage(contract *types.Contract, today time.Time) (string, string) {
+	days := int(contract.EndDate.Sub(today).Hours() / 24)
+	subject := fmt.Sprintf("Contract %s ends in %d days", contractLabel(contract), days)
+	if days == 0 {
+		subject = fmt.Sprintf("Contract %s ends today", contractLabel(contract))
+	}
+
+	body := fmt.Sprintf("Contract %s ends on %s.", contractLabel(contract), contract.EndDate.Format("2006-01-02"))
+	if contract.AutoRenew {
+		body += fmt.Sprintf(" It renews automatically for %d months unless it is cancelled before then.", contract.RenewalTermMonths)
+	} else {
+		body += " It does not renew automatically."
+	}
+	return subject, body
+}
+
+func contractLabel(contract *types.Contract) string {
+	if contract.Reference != "" {
+		return fmt.Sprintf("%s (%s)", contract.Name, contract.Reference)
+	}
+	return contract.Name
+}
+
+func (s *ContractsService) publish(ctx context.Context, eventType string, contract *types.Contract, renewedTo *uuid.UUID) {
+	if s.eventBus == nil {
+		return
+	}
+	event := types.ContractEvent{
+		OrganizationID: contract.OrganizationID,
+		ContractID:     contract.ID,
+		Status:         contract.Status,
+		EndDate:        contract.EndDate,
+		Value:          contract.Value,
+		RenewalLeadID:  contract.RenewalLeadID,
+		RenewedToID:    renewedTo,
+	}
+	if err := s.eventBus.Publish(ctx, eventType, event); err != nil {
+		s.logger.Warn("Failed to publish contract event", "event", eventType, "contract_id", contract.ID, "error", err)
+	}
+}
+
+// BuildForecast sums the contracts ending in each month from from through
+// to by where their renewal stands
+func BuildForecast(from, to time.Time, rows []types.ForecastRow) *types.Forecast {
+	var buckets []fiscal.Period
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		buckets = append(buckets, fiscal.Period{Label: m.Format("2006-01"), Start: m, End: m.AddDate(0, 1, -1)})
+	}
+	return buildForecast(from, to, buckets, rows)
+}
+
+// BuildFiscalForecast sums the contracts ending in each of the fiscal
+// periods of period by where their renewal stands
+func BuildFiscalForecast(period fiscal.Period, periods []fiscal.Period, rows []types.ForecastRow) *types.Forecast {
+	forecast := buildForecast(period.Start, period.End, periods, rows)
+	forecast.FiscalPeriod = period.Label
+	return forecast
+}
+
+func buildForecast(from, to time.Time, buckets []fiscal.Period, rows []types.ForecastRow) *types.Forecast {
+	forecast := &types.Forecast{From: from, To: to, Months: make([]types.ForecastMonth, len(buckets))}
+	for i, bucket := range buckets {
+		forecast.Months[i].Month = bucket.Label
+	}
+
+	for _, row := range rows {
+		for i, bucket := range buckets {
+			if bucket.Contains(row.EndDate) {
+				addForecast(&forecast.Months[i], row)
+				addForecast(&forecast.Total, row)
+				break
+			}
+		}
+	}
+
+	for i := range forecast.Months {
+		roundForecast(&forecast.Months[i])
+	}
+	roundForecast(&forecast.Total)
+	return forecast
+}
+
+func addForecast(m *types.ForecastMonth, row types.ForecastRow) {
+	m.Contracts++
+	m.Value += row.Value
+
+	switch {
+	case row.Status == types.ContractRenewed || row.LeadStatus == "won":
+		m.RenewedValue += row.Value
+	case row.Status == types.ContractExpired || row.LeadStatus == "lost":
+		m.LostValue += row.Value
+	case row.AutoRenew:
+		m.AutoRenewValue += row.Value
+	case row.HasLead:
+		value := row.Value
+		if row.LeadRevenue != nil {
+			value = *row.LeadRevenue
+		}
+		m.PipelineValue += value
+		m.WeightedValue += value * float64(row.LeadProbability) / 100
+	default:
+		m.UncoveredValue += row.Value
+	}
+	m.ExpectedValue = m.RenewedValue + m.AutoRenewValue + m.WeightedValue
+}
+
+func roundForecast(m *types.ForecastMonth) {
+	for _, v := range []*float64{&m.Value, &m.AutoRenewValue, &m.PipelineValue, &m.WeightedValue,
+		&m.RenewedValue, &m.LostValue, &m.UncoveredValue, &m.ExpectedValue} {
+		*v = math.Round(*v*100) / 100
+	}
+}
+
+func validateContract(request *types.ContractRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Reference = strings.TrimSpace(request.Reference)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if request.Status == "" {
+		request.Status = types.ContractActive
+	}
+	if request.Status != types.ContractDraft && request.Status != types.ContractActive {
+		return fmt.Errorf("%w: status must be draft or active", ErrInvalid)
+	}
+	if request.StartDate.IsZero() || request.EndDate.IsZero() {
+		return fmt.Errorf("%w: start_date and end_date are required", ErrInvalid)
+	}
+	request.StartDate = dateOf(request.StartDate)
+	request.EndDate = dateOf(request.EndDate)
+	if request.EndDate.Before(request.StartDate) {
+		return fmt.Errorf("%w: end_date is before start_date", ErrInvalid)
+	}
+	if request.Value < 0 {
+		return fmt.Errorf("%w: value cannot be negative", ErrInvalid)
+	}
+	if request.RenewalTermMonths == 0 {
+		request.RenewalTermMonths = 12
+	}
+	if request.RenewalTermMonths < 1 || request.RenewalTermMonths > 120 {
+		return fmt.Errorf("%w: renewal_term_months must be between 1 and 120", ErrInvalid)
+	}
+	if d := request.RenewalLeadDays; d != nil && (*d < 0 || *d > 365) {
+		return fmt.Errorf("%w: renewal_lead_days must be between 0 and 365", ErrInvalid)
+	}
+	return nil
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
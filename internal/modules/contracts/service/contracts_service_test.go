@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/contracts/types"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeContractsRepo struct {
+	contracts  []types.Contract
+	due        []types.DueContract
+	renewals   map[uuid.UUID]types.ContractRequest
+	expired    []uuid.UUID
+	leads      map[uuid.UUID]types.RenewalLead
+	reminders  []types.Reminder
+	settings   *types.SettingsRequest
+	leadOwner  map[uuid.UUID]uuid.UUID
+	leadAmount *float64
+}
+
+func newFakeContractsRepo() *fakeContractsRepo {
+	return &fakeContractsRepo{
+		renewals:  make(map[uuid.UUID]types.ContractRequest),
+		leads:     make(map[uuid.UUID]types.RenewalLead),
+		leadOwner: make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+func (f *fakeContractsRepo) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	settings := types.DefaultSettings(orgID)
+	return &settings, nil
+}
+
+func (f *fakeContractsRepo) SaveSettings(ctx context.Context, orgID, userID uuid.UUID, request types.SettingsRequest) (*types.Settings, error) {
+	f.settings = &request
+	return &types.Settings{OrganizationID: orgID, RenewalLeadDays: request.RenewalLeadDays, ReminderDays: request.ReminderDays}, nil
+}
+
+func (f *fakeContractsRepo) ListContracts(ctx context.Context, filter types.ContractFilter) ([]types.Contract, error) {
+	return f.contracts, nil
+}
+
+func (f *fakeContractsRepo) FindContract(ctx context.Context, orgID, id uuid.UUID) (*types.Contract, error) {
+	for i := range f.contracts {
+		if f.contracts[i].ID == id {
+			c := f.contracts[i]
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("contract %w", repository.ErrNotFound)
+}
+
+func (f *fakeContractsRepo) CreateContract(ctx context.Context, orgID, userID uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	c := contractFrom(orgID, request)
+	f.contracts = append(f.contracts, c)
+	return &c, nil
+}
+
+func (f *fakeContractsRepo) UpdateContract(ctx context.Context, orgID, userID, id uuid.UUID, request types.ContractRequest) (*types.Contract, error) {
+	return f.FindContract(ctx, orgID, id)
+}
+
+func (f *fakeContractsRepo) DeleteContract(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeContractsRepo) CancelContract(ctx context.Context, orgID, userID, id uuid.UUID, at time.Time) (*types.Contract, error) {
+	return f.FindContract(ctx, orgID, id)
+}
+
+func (f *fakeContractsRepo) RenewContract(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, next types.ContractRequest, at time.Time) (*types.Contract, error) {
+	if _, ok := f.renewals[id]; ok {
+		return nil, fmt.Errorf("%w: it was already renewed", repository.ErrClosed)
+	}
+	f.renewals[id] = next
+	c := contractFrom(orgID, next)
+	c.RenewedFromID = &id
+	return &c, nil
+}
+
+func (f *fakeContractsRepo) ExpireContract(ctx context.Context, orgID, id uuid.UUID, at time.Time) (*types.Contract, error) {
+	f.expired = append(f.expired, id)
+	return &types.Contract{ID: id, OrganizationID: orgID, Status: types.ContractExpired}, nil
+}
+
+func (f *fakeContractsRepo) ListDocuments(ctx context.Context, orgID, contractID uuid.UUID) ([]types.ContractDocument, error) {
+	return []types.ContractDocument{}, nil
+}
+
+func (f *fakeContractsRepo) AddDocument(ctx context.Context, orgID, userID, contractID uuid.UUID, request types.DocumentRequest) (*types.ContractDocument, error) {
+	return &types.ContractDocument{ID: uuid.New(), ContractID: contractID, AttachmentID: request.AttachmentID, GeneratedDocumentID: request.GeneratedDocumentID}, nil
+}
+
+func (f *fakeContractsRepo) RemoveDocument(ctx context.Context, orgID, contractID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeContractsRepo) ListReminders(ctx context.Context, orgID, contractID uuid.UUID) ([]types.Reminder, error) {
+	return f.reminders, nil
+}
+
+func (f *fakeContractsRepo) RecordReminder(ctx context.Context, orgID uuid.UUID, reminder types.Reminder) error {
+	f.reminders = append(f.reminders, reminder)
+	return nil
+}
+
+func (f *fakeContractsRepo) DueContracts(ctx context.Context, today time.Time, limit int) ([]types.DueContract, error) {
+	return f.due, nil
+}
+
+func (f *fakeContractsRepo) CreateRenewalLead(ctx context.Context, orgID, contractID uuid.UUID, lead types.RenewalLead, at time.Time) (uuid.UUID, error) {
+	leadID := uuid.New()
+	f.leads[contractID] = lead
+	f.leadOwner[leadID] = contractID
+	return leadID, nil
+}
+
+func (f *fakeContractsRepo) ContractForRenewalLead(ctx context.Context, orgID, leadID uuid.UUID) (*types.Contract, *float64, error) {
+	contractID, ok := f.leadOwner[leadID]
+	if !ok {
+		return nil, nil, fmt.Errorf("contract %w", repository.ErrNotFound)
+	}
+	c, err := f.FindContract(ctx, orgID, contractID)
+	return c, f.leadAmount, err
+}
+
+func (f *fakeContractsRepo) ForecastRows(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.ForecastRow, error) {
+	return nil, nil
+}
+
+func contractFrom(orgID uuid.UUID, request types.ContractRequest) types.Contract {
+	return types.Contract{
+		ID:                uuid.New(),
+		OrganizationID:    orgID,
+		Name:              request.Name,
+		Reference:         request.Reference,
+		ContactID:         request.ContactID,
+		OwnerID:           request.OwnerID,
+		Status:            request.Status,
+		StartDate:         request.StartDate,
+		EndDate:           request.EndDate,
+		Value:             request.Value,
+		AutoRenew:         request.AutoRenew,
+		RenewalTermMonths: request.RenewalTermMonths,
+		RenewalLeadDays:   request.RenewalLeadDays,
+	}
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+type fakeMailer struct {
+	sent []sentMail
+}
+
+func (f *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	f.sent = append(f.sent, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error {
+	return nil
+}
+
+var testNow = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestService(repo *fakeContractsRepo) *ContractsService {
+	s := NewContractsService(repo, allowAll{}, nil, nil)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// dueContract is an active contract ending on end with the default settings
+func dueContract(end time.Time, autoRenew bool) types.DueContract {
+	ownerID := uuid.New()
+	return types.DueContract{
+		Contract: types.Contract{
+			ID:                uuid.New(),
+			OrganizationID:    uuid.New(),
+			Name:              "Support plan",
+			Reference:         "C-104",
+			OwnerID:           &ownerID,
+			Status:            types.ContractActive,
+			StartDate:         end.AddDate(-1, 0, 1),
+			EndDate:           end,
+			Value:             12000,
+			AutoRenew:         autoRenew,
+			RenewalTermMonths: 12,
+		},
+		Settings:   types.DefaultSettings(uuid.Nil),
+		OwnerEmail: "owner@example.com",
+	}
+}
+
+func TestCreateContractValidates(t *testing.T) {
+	s := newTestService(newFakeContractsRepo())
+	orgID, userID := uuid.New(), uuid.New()
+	negative := -1
+
+	for name, req := range map[string]types.ContractRequest{
+		"no name":        {StartDate: day(1, 1), EndDate: day(12, 31)},
+		"no dates":       {Name: "Plan"},
+		"reversed dates": {Name: "Plan", StartDate: day(12, 31), EndDate: day(1, 1)},
+		"closed status":  {Name: "Plan", Status: types.ContractRenewed, StartDate: day(1, 1), EndDate: day(12, 31)},
+		"negative value": {Name: "Plan", StartDate: day(1, 1), EndDate: day(12, 31), Value: -5},
+		"long term":      {Name: "Plan", StartDate: day(1, 1), EndDate: day(12, 31), RenewalTermMonths: 121},
+		"lead days":      {Name: "Plan", StartDate: day(1, 1), EndDate: day(12, 31), RenewalLeadDays: &negative},
+	} {
+		_, err := s.CreateContract(context.Background(), orgID, userID, req)
+		assert.True(t, errors.Is(err, ErrInvalid), name)
+	}
+
+	contract, err := s.CreateContract(context.Background(), orgID, userID, types.ContractRequest{
+		Name:      " Plan ",
+		StartDate: time.Date(2025, 1, 1, 15, 30, 0, 0, time.UTC),
+		EndDate:   day(12, 31),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Plan", contract.Name)
+	assert.Equal(t, types.ContractActive, contract.Status)
+	assert.Equal(t, day(1, 1), contract.StartDate)
+	assert.Equal(t, 12, contract.RenewalTermMonths)
+}
+
+func TestNextTermFollowsContract(t *testing.T) {
+	contract := dueContract(day(3, 31), true).Contract
+	contract.RenewalTermMonths = 6
+
+	next := NextTerm(&contract)
+
+	assert.Equal(t, day(4, 1), next.StartDate)
+	assert.Equal(t, day(9, 30), next.EndDate)
+	assert.Equal(t, contract.Value, next.Value)
+	assert.True(t, next.AutoRenew)
+	assert.Equal(t, types.ContractActive, next.Status)
+}
+
+func TestRenewalLeadDue(t *testing.T) {
+	today := day(3, 10)
+	contract := dueContract(today.AddDate(0, 0, 61), false)
+	assert.False(t, RenewalLeadDue(&contract, today))
+
+	contract = dueContract(today.AddDate(0, 0, 60), false)
+	assert.True(t, RenewalLeadDue(&contract, today))
+
+	leadDays := 90
+	contract = dueContract(today.AddDate(0, 0, 75), false)
+	contract.RenewalLeadDays = &leadDays
+	assert.True(t, RenewalLeadDue(&contract, today), "the contract's lead time overrides the settings")
+
+	contract = dueContract(today.AddDate(0, 0, 10), true)
+	assert.False(t, RenewalLeadDue(&contract, today), "auto-renewing contracts need no opportunity")
+
+	leadID := uuid.New()
+	contract = dueContract(today.AddDate(0, 0, 10), false)
+	contract.RenewalLeadID = &leadID
+	assert.False(t, RenewalLeadDue(&contract, today))
+}
+
+func TestDueReminderStepsSkipsRecorded(t *testing.T) {
+	today := day(3, 10)
+	contract := dueContract(today.AddDate(0, 0, 20), false)
+
+	assert.Equal(t, []int{90, 30}, DueReminderSteps(&contract, today))
+
+	contract.RemindedDays = []int{90, 30}
+	assert.Empty(t, DueReminderSteps(&contract, today))
+
+	contract = dueContract(today.AddDate(0, 0, 100), false)
+	assert.Empty(t, DueReminderSteps(&contract, today))
+}
+
+func TestRunRenewalsRenewsAndExpiresEndedContracts(t *testing.T) {
+	repo := newFakeContractsRepo()
+	s := newTestService(repo)
+	autoRenew := dueContract(day(3, 9), true)
+	manual := dueContract(day(3, 9), false)
+	repo.due = []types.DueContract{autoRenew, manual}
+
+	result, err := s.RunRenewals(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, &types.RenewalResult{AutoRenewed: 1, Expired: 1}, result)
+	require.Contains(t, repo.renewals, autoRenew.ID)
+	assert.Equal(t, day(3, 10), repo.renewals[autoRenew.ID].StartDate)
+	assert.Equal(t, day(3, 9).AddDate(1, 0, 0), repo.renewals[autoRenew.ID].EndDate)
+	assert.Equal(t, []uuid.UUID{manual.ID}, repo.expired)
+	assert.Empty(t, repo.reminders, "ended contracts are not reminded of")
+}
+
+func TestRunRenewalsCreatesRenewalOpportunity(t *testing.T) {
+	repo := newFakeContractsRepo()
+	s := newTestService(repo)
+	contract := dueContract(day(4, 30), false)
+	contract.RemindedDays = []int{90}
+	repo.due = []types.DueContract{contract}
+
+	result, err := s.RunRenewals(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.LeadsCreated)
+	lead := repo.leads[contract.ID]
+	assert.Equal(t, "Renewal: Support plan", lead.Name)
+	assert.Equal(t, contract.OwnerID, lead.AssignedTo)
+	assert.Equal(t, 12000.0, lead.ExpectedRevenue)
+	assert.Equal(t, day(4, 30), lead.Deadline)
+}
+
+func TestRunRenewalsSendsLatestReminderStepOnly(t *testing.T) {
+	repo := newFakeContractsRepo()
+	mailer := &fakeMailer{}
+	s := newTestService(repo)
+	s.SetMailer(mailer)
+	contract := dueContract(day(3, 30), true)
+	contract.CustomerEmail = "customer@example.com"
+	contract.Settings.NotifyCustomer = true
+	repo.due = []types.DueContract{contract}
+
+	result, err := s.RunRenewals(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.RemindersSent)
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, "owner@example.com", mailer.sent[0].to)
+	assert.Equal(t, "customer@example.com", mailer.sent[1].to)
+	assert.Equal(t, "Contract Support plan (C-104) ends in 20 days", mailer.sent[0].subject)
+	assert.Contains(t, mailer.sent[0].body, "renews automatically for 12 months")
+
+	require.Len(t, repo.reminders, 2)
+	assert.Equal(t, 90, repo.reminders[0].DaysBefore)
+	assert.Equal(t, types.ReminderSkipped, repo.reminders[0].Status)
+	assert.Equal(t, 30, repo.reminders[1].DaysBefore)
+	assert.Equal(t, types.ReminderSent, repo.reminders[1].Status)
+	assert.Equal(t, []string{"owner@example.com", "customer@example.com"}, repo.reminders[1].Recipients)
+}
+
+func TestRunRenewalsSkipsRemindersWithoutMailer(t *testing.T) {
+	repo := newFakeContractsRepo()
+	s := newTestService(repo)
+	contract := dueContract(day(3, 15), true)
+	contract.RemindedDays = []int{90, 30}
+	repo.due = []types.DueContract{contract}
+
+	result, err := s.RunRenewals(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.RemindersSent)
+	require.Len(t, repo.reminders, 1)
+	assert.Equal(t, 7, repo.reminders[0].DaysBefore)
+	assert.Equal(t, types.ReminderSkipped, repo.reminders[0].Status)
+}
+
+func TestHandleRenewalWonRenewsAtOpportunityRevenue(t *testing.T) {
+	repo := newFakeContractsRepo()
+	s := newTestService(repo)
+	contract := dueContract(day(4, 30), false).Contract
+	repo.contracts = []types.Contract{contract}
+	leadID := uuid.New()
+	repo.leadOwner[leadID] = contract.ID
+	revenue := 15000.0
+	repo.leadAmount = &revenue
+
+	renewed, err := s.HandleRenewalWon(context.Background(), contract.OrganizationID, leadID)
+	require.NoError(t, err)
+
+	require.NotNil(t, renewed)
+	assert.Equal(t, 15000.0, renewed.Value)
+	assert.Equal(t, day(5, 1), renewed.StartDate)
+
+	other, err := s.HandleRenewalWon(context.Background(), contract.OrganizationID, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, other, "leads renewing no contract are ignored")
+}
+
+func TestSaveSettingsSortsReminderDays(t *testing.T) {
+	repo := newFakeContractsRepo()
+	s := newTestService(repo)
+
+	_, err := s.SaveSettings(context.Background(), uuid.New(), uuid.New(), types.SettingsRequest{RenewalLeadDays: 45, ReminderDays: []int{7, 60, 7, 30}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{60, 30, 7}, repo.settings.ReminderDays)
+
+	_, err = s.SaveSettings(context.Background(), uuid.New(), uuid.New(), types.SettingsRequest{RenewalLeadDays: 45, ReminderDays: []int{400}})
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func TestBuildForecast(t *testing.T) {
+	revenue := 8000.0
+	rows := []types.ForecastRow{
+		{EndDate: day(4, 30), Status: types.ContractActive, Value: 1000, AutoRenew: true},
+		{EndDate: day(4, 15), Status: types.ContractActive, Value: 10000, HasLead: true, LeadProbability: 50, LeadRevenue: &revenue},
+		{EndDate: day(4, 1), Status: types.ContractActive, Value: 500},
+		{EndDate: day(5, 20), Status: types.ContractRenewed, Value: 2000},
+		{EndDate: day(5, 2), Status: types.ContractExpired, Value: 300},
+		{EndDate: day(5, 3), Status: types.ContractActive, Value: 700, HasLead: true, LeadStatus: "lost"},
+	}
+
+	forecast := BuildForecast(day(4, 1), day(6, 30), rows)
+
+	require.Len(t, forecast.Months, 3)
+	april := forecast.Months[0]
+	assert.Equal(t, "2025-04", april.Month)
+	assert.Equal(t, 3, april.Contracts)
+	assert.Equal(t, 11500.0, april.Value)
+	assert.Equal(t, 1000.0, april.AutoRenewValue)
+	assert.Equal(t, 8000.0, april.PipelineValue)
+	assert.Equal(t, 4000.0, april.WeightedValue)
+	assert.Equal(t, 500.0, april.UncoveredValue)
+	assert.Equal(t, 5000.0, april.ExpectedValue)
+
+	may := forecast.Months[1]
+	assert.Equal(t, 2000.0, may.RenewedValue)
+	assert.Equal(t, 1000.0, may.LostValue)
+	assert.Equal(t, 2000.0, may.ExpectedValue)
+
+	assert.Equal(t, "2025-06", forecast.Months[2].Month)
+	assert.Zero(t, forecast.Months[2].Contracts)
+	assert.Equal(t, 6, forecast.Total.Contracts)
+	assert.Equal(t, 7000.0, forecast.Total.ExpectedValue)
+}
+
+func TestBuildFiscalForecast(t *testing.T) {
+	// A 4-4-5 year ending on the last Saturday of December: FY2025-Q2 runs
+	// from 2025-03-30 through 2025-06-28
+	cal := &fiscal.Calendar{Pattern: fiscal.Pattern445, WeekEnd: fiscal.Weekday(time.Saturday)}
+	require.NoError(t, cal.Validate())
+	q2 := cal.Quarter(2025, 2)
+	rows := []types.ForecastRow{
+		{EndDate: day(3, 30), Status: types.ContractActive, Value: 100},
+		{EndDate: day(4, 27), Status: types.ContractActive, Value: 200},
+		{EndDate: day(6, 28), Status: types.ContractRenewed, Value: 300},
+	}
+
+	forecast := BuildFiscalForecast(q2, cal.Buckets(q2.Start, q2.End), rows)
+
+	assert.Equal(t, "FY2025-Q2", forecast.FiscalPeriod)
+	require.Len(t, forecast.Months, 3)
+	assert.Equal(t, "FY2025-P04", forecast.Months[0].Month)
+	// April 27 starts the fifth fiscal month, not the calendar month of April
+	assert.Equal(t, 100.0, forecast.Months[0].Value)
+	assert.Equal(t, 200.0, forecast.Months[1].Value)
+	assert.Equal(t, 300.0, forecast.Months[2].RenewedValue)
+	assert.Equal(t, 3, forecast.Total.Contracts)
+}
@@ -0,0 +1,262 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContractStatus is where a contract term stands
+type ContractStatus string
+
+const (
+	// ContractDraft contracts are not signed yet; they are neither renewed
+	// nor reminded of
+	ContractDraft     ContractStatus = "draft"
+	ContractActive    ContractStatus = "active"
+	ContractRenewed   ContractStatus = "renewed"
+	ContractExpired   ContractStatus = "expired"
+	ContractCancelled ContractStatus = "cancelled"
+)
+
+// IsValid reports whether the status is known
+func (s ContractStatus) IsValid() bool {
+	switch s {
+	case ContractDraft, ContractActive, ContractRenewed, ContractExpired, ContractCancelled:
+		return true
+	}
+	return false
+}
+
+// IsClosed reports whether the term is over, one way or another
+func (s ContractStatus) IsClosed() bool {
+	return s == ContractRenewed || s == ContractExpired || s == ContractCancelled
+}
+
+// Contract is one term of a customer contract. StartDate and EndDate are
+// dates, both included in the term.
+type Contract struct {
+	ID                uuid.UUID      `json:"id"`
+	OrganizationID    uuid.UUID      `json:"organization_id"`
+	Name              string         `json:"name"`
+	Reference         string         `json:"reference"`
+	ContactID         *uuid.UUID     `json:"contact_id,omitempty"`
+	OwnerID           *uuid.UUID     `json:"owner_id,omitempty"`
+	Status            ContractStatus `json:"status"`
+	StartDate         time.Time      `json:"start_date"`
+	EndDate           time.Time      `json:"end_date"`
+	Value             float64        `json:"value"`
+	AutoRenew         bool           `json:"auto_renew"`
+	RenewalTermMonths int            `json:"renewal_term_months"`
+	// RenewalLeadDays overrides the organization's lead time of renewal
+	// opportunities
+	RenewalLeadDays *int       `json:"renewal_lead_days,omitempty"`
+	Notes           string     `json:"notes"`
+	RenewalLeadID   *uuid.UUID `json:"renewal_lead_id,omitempty"`
+	RenewedFromID   *uuid.UUID `json:"renewed_from_id,omitempty"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	UpdatedBy       *uuid.UUID `json:"updated_by,omitempty"`
+}
+
+// ContractRequest creates or updates a contract. Status is draft or
+// active; the other statuses are reached by renewing, cancelling or
+// letting the term expire.
+type ContractRequest struct {
+	Name              string         `json:"name"`
+	Reference         string         `json:"reference"`
+	ContactID         *uuid.UUID     `json:"contact_id,omitempty"`
+	OwnerID           *uuid.UUID     `json:"owner_id,omitempty"`
+	Status            ContractStatus `json:"status,omitempty"`
+	StartDate         time.Time      `json:"start_date"`
+	EndDate           time.Time      `json:"end_date"`
+	Value             float64        `json:"value"`
+	AutoRenew         bool           `json:"auto_renew"`
+	RenewalTermMonths int            `json:"renewal_term_months,omitempty"`
+	RenewalLeadDays   *int           `json:"renewal_lead_days,omitempty"`
+	Notes             string         `json:"notes"`
+}
+
+// RenewRequest renews a contract into its next term. The term starts the
+// day after the contract ends and runs for the renewal term unless EndDate
+// is given; Value defaults to the current value.
+type RenewRequest struct {
+	EndDate *time.Time `json:"end_date,omitempty"`
+	Value   *float64   `json:"value,omitempty"`
+}
+
+// ContractFilter narrows a list of contracts
+type ContractFilter struct {
+	OrganizationID uuid.UUID
+	Status         ContractStatus
+	ContactID      *uuid.UUID
+	OwnerID        *uuid.UUID
+	// EndingBefore keeps the contracts ending on or before the date
+	EndingBefore *time.Time
+	Limit        int
+	Offset       int
+}
+
+// ContractDocument links an uploaded attachment or a generated document to
+// a contract
+type ContractDocument struct {
+	ID                  uuid.UUID  `json:"id"`
+	ContractID          uuid.UUID  `json:"contract_id"`
+	AttachmentID        *uuid.UUID `json:"attachment_id,omitempty"`
+	GeneratedDocumentID *uuid.UUID `json:"generated_document_id,omitempty"`
+	Label               string     `json:"label"`
+	FileName            string     `json:"file_name"`
+	CreatedBy           *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// DocumentRequest links a document to a contract; exactly one of
+// AttachmentID and GeneratedDocumentID is set
+type DocumentRequest struct {
+	AttachmentID        *uuid.UUID `json:"attachment_id,omitempty"`
+	GeneratedDocumentID *uuid.UUID `json:"generated_document_id,omitempty"`
+	Label               string     `json:"label"`
+}
+
+// Settings are an organization's renewal lead time and expiry reminder
+// sequence
+type Settings struct {
+	OrganizationID  uuid.UUID  `json:"organization_id"`
+	RenewalLeadDays int        `json:"renewal_lead_days"`
+	ReminderDays    []int      `json:"reminder_days"`
+	NotifyCustomer  bool       `json:"notify_customer"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy       *uuid.UUID `json:"updated_by,omitempty"`
+}
+
+// DefaultSettings are the settings of organizations that did not set any
+func DefaultSettings(orgID uuid.UUID) Settings {
+	return Settings{
+		OrganizationID:  orgID,
+		RenewalLeadDays: 60,
+		ReminderDays:    []int{90, 30, 7},
+	}
+}
+
+// LeadDays is how long before the contract ends its renewal opportunity is
+// created
+func (c *Contract) LeadDays(settings Settings) int {
+	if c.RenewalLeadDays != nil {
+		return *c.RenewalLeadDays
+	}
+	return settings.RenewalLeadDays
+}
+
+// SettingsRequest replaces an organization's contract settings
+type SettingsRequest struct {
+	RenewalLeadDays int   `json:"renewal_lead_days"`
+	ReminderDays    []int `json:"reminder_days"`
+	NotifyCustomer  bool  `json:"notify_customer"`
+}
+
+// ReminderStatus is the outcome of a reminder step
+type ReminderStatus string
+
+const (
+	ReminderSent    ReminderStatus = "sent"
+	ReminderSkipped ReminderStatus = "skipped"
+	ReminderFailed  ReminderStatus = "failed"
+)
+
+// Reminder is a step of a contract's expiry reminder sequence
+type Reminder struct {
+	ContractID uuid.UUID      `json:"contract_id"`
+	DaysBefore int            `json:"days_before"`
+	Status     ReminderStatus `json:"status"`
+	Recipients []string       `json:"recipients"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// DueContract is an active contract the renewal pass looks at, with the
+// organization's settings and whom its reminders go to
+type DueContract struct {
+	Contract
+	Settings      Settings
+	OwnerEmail    string
+	CustomerEmail string
+	// RemindedDays are the reminder steps already recorded
+	RemindedDays []int
+}
+
+// RenewalLead is the opportunity created to renew a contract
+type RenewalLead struct {
+	Name            string
+	ContactID       *uuid.UUID
+	AssignedTo      *uuid.UUID
+	ExpectedRevenue float64
+	Deadline        time.Time
+	Description     string
+}
+
+// ForecastRow is a contract ending in the forecast window with where its
+// renewal stands
+type ForecastRow struct {
+	ContractID uuid.UUID
+	EndDate    time.Time
+	Status     ContractStatus
+	Value      float64
+	AutoRenew  bool
+	// LeadStatus is the renewal opportunity's won status, empty when it is
+	// still open or there is none
+	LeadStatus      string
+	LeadProbability int
+	LeadRevenue     *float64
+	HasLead         bool
+}
+
+// ForecastMonth is the renewal forecast of the contracts ending in a month.
+// Expected is the renewed value, the auto-renewing value and the open
+// renewal opportunities weighted by their probability.
+type ForecastMonth struct {
+	// Month is YYYY-MM, or the label of a fiscal period such as FY2025-P03
+	// in fiscal forecasts
+	Month          string  `json:"month"`
+	Contracts      int     `json:"contracts"`
+	Value          float64 `json:"value"`
+	AutoRenewValue float64 `json:"auto_renew_value"`
+	PipelineValue  float64 `json:"pipeline_value"`
+	WeightedValue  float64 `json:"weighted_value"`
+	RenewedValue   float64 `json:"renewed_value"`
+	LostValue      float64 `json:"lost_value"`
+	UncoveredValue float64 `json:"uncovered_value"`
+	ExpectedValue  float64 `json:"expected_value"`
+}
+
+// Forecast is the renewal forecast over a window of months, or of fiscal
+// periods when FiscalPeriod names the fiscal year, quarter or period
+// forecast
+type Forecast struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	FiscalPeriod string          `json:"fiscal_period,omitempty"`
+	Months       []ForecastMonth `json:"months"`
+	Total        ForecastMonth   `json:"total"`
+}
+
+// RenewalResult counts what a renewal pass did
+type RenewalResult struct {
+	LeadsCreated  int `json:"leads_created"`
+	AutoRenewed   int `json:"auto_renewed"`
+	Expired       int `json:"expired"`
+	RemindersSent int `json:"reminders_sent"`
+}
+
+// ContractEvent is published when a renewal opportunity is created and
+// when a contract is renewed, expires or is cancelled
+type ContractEvent struct {
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	ContractID     uuid.UUID      `json:"contract_id"`
+	Status         ContractStatus `json:"status"`
+	EndDate        time.Time      `json:"end_date"`
+	Value          float64        `json:"value"`
+	RenewalLeadID  *uuid.UUID     `json:"renewal_lead_id,omitempty"`
+	RenewedToID    *uuid.UUID     `json:"renewed_to_id,omitempty"`
+}
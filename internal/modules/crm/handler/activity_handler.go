@@ -5,10 +5,10 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/google/uuid"
-	"github.com/julienschmidt/httprouter"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 type ActivityHandler struct {
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -45,6 +47,14 @@ func respondWithError(w http.ResponseWriter, statusCode int, message string, err
 	}
 }
 
+// assignmentRuleErrorStatus maps rule validation errors to 400
+func assignmentRuleErrorStatus(err error) int {
+	if errors.Is(err, types.ErrInvalidAssignmentRule) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // RegisterRoutes registers assignment rule routes
 func (h *AssignmentRuleHandler) RegisterRoutes(router *httprouter.Router) {
 	// Assignment Rule routes
@@ -74,8 +84,11 @@ func (h *AssignmentRuleHandler) CreateAssignmentRule(w http.ResponseWriter, r *h
 	}
 
 	rule, err := h.service.CreateAssignmentRule(r.Context(), &req)
+	if entitlements.WriteError(w, err) {
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to create assignment rule: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to create assignment rule: "+err.Error(), assignmentRuleErrorStatus(err))
 		return
 	}
 
@@ -124,8 +137,11 @@ func (h *AssignmentRuleHandler) UpdateAssignmentRule(w http.ResponseWriter, r *h
 	}
 
 	rule, err := h.service.UpdateAssignmentRule(r.Context(), id, &req)
+	if entitlements.WriteError(w, err) {
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to update assignment rule: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to update assignment rule: "+err.Error(), assignmentRuleErrorStatus(err))
 		return
 	}
 
@@ -156,12 +172,11 @@ func (h *AssignmentRuleHandler) DeleteAssignmentRule(w http.ResponseWriter, r *h
 
 // ListAssignmentRules handles GET /assignment-rules
 func (h *AssignmentRuleHandler) ListAssignmentRules(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	targetModel := r.URL.Query().Get("target_model")
 	activeOnly := r.URL.Query().Get("active_only") == "true"
@@ -251,12 +266,11 @@ func (h *AssignmentRuleHandler) DeleteTerritory(w http.ResponseWriter, r *http.R
 
 // ListTerritories handles GET /territories
 func (h *AssignmentRuleHandler) ListTerritories(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	activeOnly := r.URL.Query().Get("active_only") == "true"
 
@@ -294,12 +308,11 @@ func (h *AssignmentRuleHandler) AssignLead(w http.ResponseWriter, r *http.Reques
 
 // GetAssignmentStatsByUser handles GET /assignment-rules/stats/users
 func (h *AssignmentRuleHandler) GetAssignmentStatsByUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	targetModel := r.URL.Query().Get("target_model")
 
@@ -314,12 +327,11 @@ func (h *AssignmentRuleHandler) GetAssignmentStatsByUser(w http.ResponseWriter,
 
 // GetAssignmentRuleEffectiveness handles GET /assignment-rules/stats/rules
 func (h *AssignmentRuleHandler) GetAssignmentRuleEffectiveness(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	effectiveness, err := h.service.GetAssignmentRuleEffectiveness(r.Context(), orgID)
 	if err != nil {
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ContactConsentHandler serves contacts' communication preferences, and the
+// public links contacts confirm opt-ins and unsubscribe with
+type ContactConsentHandler struct {
+	service *service.ContactConsentService
+}
+
+func NewContactConsentHandler(service *service.ContactConsentService) *ContactConsentHandler {
+	return &ContactConsentHandler{
+		service: service,
+	}
+}
+
+func (h *ContactConsentHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET(contactsPath+"/:id/communication-preferences", h.GetPreferences)
+	router.PUT(contactsPath+"/:id/communication-preferences", h.SetConsent)
+	router.GET(contactsPath+"/:id/communication-preferences/history", h.ListHistory)
+
+	// Public: reached with the links emailed to contacts
+	router.GET(service.ConsentConfirmPath+":token", h.ConfirmOptIn)
+	router.GET(service.UnsubscribePath+":token", h.GetPublicPreferences)
+	router.POST(service.UnsubscribePath+":token", h.Unsubscribe)
+}
+
+// GetPreferences handles the contact's preference for every channel and
+// its unsubscribe link
+func (h *ContactConsentHandler) GetPreferences(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	preferences, err := h.service.GetPreferences(r.Context(), contactID)
+	if err != nil {
+		writeContactConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, preferences)
+}
+
+// SetConsent handles recording the contact's preference for a channel
+func (h *ContactConsentHandler) SetConsent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ContactConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	consent, err := h.service.SetConsent(r.Context(), contactID, req)
+	if err != nil {
+		writeContactConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, consent)
+}
+
+// ListHistory handles the audit history of the contact's consents
+func (h *ContactConsentHandler) ListHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.ListHistory(r.Context(), contactID)
+	if err != nil {
+		writeContactConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data": history,
+	})
+}
+
+// ConfirmOptIn handles the double opt-in link a contact was emailed
+func (h *ContactConsentHandler) ConfirmOptIn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	consent, err := h.service.ConfirmOptIn(r.Context(), ps.ByName("token"), consentLink(r))
+	if err != nil {
+		writePublicConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, consent)
+}
+
+// GetPublicPreferences handles the unsubscribe page's status of each channel
+func (h *ContactConsentHandler) GetPublicPreferences(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	preferences, err := h.service.GetPublicPreferences(r.Context(), ps.ByName("token"))
+	if err != nil {
+		writePublicConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, preferences)
+}
+
+// Unsubscribe handles a contact opting out of the channel query parameter,
+// email when it is not given. It also serves as the one-click
+// List-Unsubscribe-Post target of emails.
+func (h *ContactConsentHandler) Unsubscribe(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	channel := types.ContactConsentChannel(r.URL.Query().Get("channel"))
+
+	consent, err := h.service.Unsubscribe(r.Context(), ps.ByName("token"), channel, consentLink(r))
+	if err != nil {
+		writePublicConsentError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, consent)
+}
+
+// consentLink is the client a contact used a public link from
+func consentLink(r *http.Request) service.ConsentLink {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return service.ConsentLink{IPAddress: host, UserAgent: r.UserAgent()}
+}
+
+func writeContactConsentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidConsent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrConsentUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writePublicConsentError answers a contact without exposing internal errors
+func writePublicConsentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrConsentTokenNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, types.ErrInvalidConsent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Your preferences could not be updated", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+// consentRecords keeps the consents of one contact in memory
+type consentRecords struct {
+	orgID, contactID uuid.UUID
+	consents         map[types.ContactConsentChannel]types.ContactConsent
+	events           []types.ContactConsentEvent
+	unsubscribeToken string
+}
+
+func (c *consentRecords) ListConsents(_ context.Context, orgID, contactID uuid.UUID) ([]types.ContactConsent, error) {
+	if orgID != c.orgID || contactID != c.contactID {
+		return nil, sql.ErrNoRows
+	}
+	consents := []types.ContactConsent{}
+	for _, consent := range c.consents {
+		consents = append(consents, consent)
+	}
+	return consents, nil
+}
+
+func (c *consentRecords) record(event types.ContactConsentEvent, previous types.ContactConsentStatus) {
+	event.PreviousStatus = previous
+	c.events = append([]types.ContactConsentEvent{event}, c.events...)
+}
+
+func (c *consentRecords) SaveConsent(_ context.Context, consent types.ContactConsent, event types.ContactConsentEvent) (*types.ContactConsent, error) {
+	previous := c.consents[consent.Channel].Status
+	if previous == "" {
+		previous = types.ContactConsentUnknown
+	}
+	consent.UpdatedAt = &event.CreatedAt
+	c.consents[consent.Channel] = consent
+	c.record(event, previous)
+	return &consent, nil
+}
+
+func (c *consentRecords) ConfirmConsent(_ context.Context, tokenHash string, sentAfter time.Time, event types.ContactConsentEvent) (*types.ContactConsent, error) {
+	for channel, consent := range c.consents {
+		if consent.Status == types.ContactConsentPending && consent.ConfirmationTokenHash == tokenHash && consent.ConfirmationSentAt.After(sentAfter) {
+			consent.Status, consent.Source, consent.ConfirmationTokenHash = types.ContactConsentOptedIn, event.Source, ""
+			consent.OptedInAt, consent.ConfirmedAt = &event.CreatedAt, &event.CreatedAt
+			c.consents[channel] = consent
+			event.OrganizationID, event.ContactID, event.Channel = consent.OrganizationID, consent.ContactID, channel
+			c.record(event, types.ContactConsentPending)
+			return &consent, nil
+		}
+	}
+	return nil, types.ErrConsentTokenNotFound
+}
+
+func (c *consentRecords) ListConsentEvents(context.Context, uuid.UUID, uuid.UUID) ([]types.ContactConsentEvent, error) {
+	return c.events, nil
+}
+
+func (c *consentRecords) UnsubscribeToken(_ context.Context, _, _ uuid.UUID, token string) (string, error) {
+	if c.unsubscribeToken == "" {
+		c.unsubscribeToken = token
+	}
+	return c.unsubscribeToken, nil
+}
+
+func (c *consentRecords) FindUnsubscribeToken(_ context.Context, token string) (uuid.UUID, uuid.UUID, error) {
+	if token == "" || token != c.unsubscribeToken {
+		return uuid.Nil, uuid.Nil, types.ErrConsentTokenNotFound
+	}
+	return c.orgID, c.contactID, nil
+}
+
+// consentContacts serves the contact of consentRecords
+type consentContacts struct {
+	types.ContactRepository
+	contact types.Contact
+}
+
+func (c consentContacts) FindByID(context.Context, uuid.UUID) (*types.Contact, error) {
+	return &c.contact, nil
+}
+
+type consentMailer struct {
+	to, body []string
+}
+
+func (m *consentMailer) Send(_ context.Context, to, _, body string) error {
+	m.to, m.body = append(m.to, to), append(m.body, body)
+	return nil
+}
+
+func consentRouter(records *consentRecords, mailer *consentMailer) (*httprouter.Router, *service.ContactConsentService) {
+	email := "ada@example.test"
+	contacts := consentContacts{contact: types.Contact{ID: records.contactID, OrganizationID: records.orgID, Name: "Ada Lovelace", Email: &email}}
+	svc := service.NewContactConsentService(records, contacts, contextCaller{}, nil)
+	if mailer != nil {
+		svc.SetMailer(mailer)
+		svc.SetPublicURL("https://api.example.test/")
+	}
+	router := httprouter.New()
+	NewContactConsentHandler(svc).RegisterRoutes(router)
+	return router, svc
+}
+
+func servePublic(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = "203.0.113.7:52100"
+	r.Header.Set("User-Agent", "Mail/1.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestContactDoubleOptInIsConfirmedOnceFromTheEmailedLink(t *testing.T) {
+	records := &consentRecords{orgID: uuid.New(), contactID: uuid.New(), consents: map[types.ContactConsentChannel]types.ContactConsent{}}
+	mailer := &consentMailer{}
+	router, svc := consentRouter(records, mailer)
+	userID := uuid.New()
+	path := "/api/v2/contacts/" + records.contactID.String() + "/communication-preferences"
+
+	w := serveAs(router, records.orgID, userID, http.MethodPut, path, `{"channel": "sms", "status": "opted_in", "double_opt_in": true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serveAs(router, records.orgID, userID, http.MethodPut, path, `{"channel": "email", "status": "opted_in", "double_opt_in": true, "note": "Signed up at the fair"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pending types.ContactConsent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.Equal(t, types.ContactConsentPending, pending.Status)
+	assert.NotNil(t, pending.ConfirmationSentAt)
+	assert.NotContains(t, w.Body.String(), records.consents[types.ContactConsentEmail].ConfirmationTokenHash)
+
+	// The contact is not emailed until they confirm
+	allowed, err := svc.Allows(context.Background(), records.orgID, records.contactID, "email")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	require.Equal(t, []string{"ada@example.test"}, mailer.to)
+	link := regexp.MustCompile(`https://api\.example\.test(/public/v1/consent/confirm/[0-9a-f]+)`).FindStringSubmatch(mailer.body[0])
+	require.Len(t, link, 2, mailer.body[0])
+
+	w = servePublic(router, http.MethodGet, link[1])
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"channel": "email", "status": "opted_in"}`, w.Body.String())
+	w = servePublic(router, http.MethodGet, link[1])
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	allowed, err = svc.Allows(context.Background(), records.orgID, records.contactID, "email")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	confirmed := records.consents[types.ContactConsentEmail]
+	assert.NotNil(t, confirmed.ConfirmedAt)
+	assert.Equal(t, types.ContactConsentSourceDoubleOptIn, confirmed.Source)
+
+	w = serveAs(router, records.orgID, userID, http.MethodGet, path+"/history", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var history struct {
+		Data []types.ContactConsentEvent `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history.Data, 2)
+	assert.Equal(t, types.ContactConsentSourceDoubleOptIn, history.Data[0].Source)
+	assert.Equal(t, types.ContactConsentPending, history.Data[0].PreviousStatus)
+	assert.Equal(t, "203.0.113.7", history.Data[0].IPAddress)
+	assert.Equal(t, "Mail/1.0", history.Data[0].UserAgent)
+	assert.Equal(t, types.ContactConsentUnknown, history.Data[1].PreviousStatus)
+	assert.Equal(t, &userID, history.Data[1].ActorID)
+	assert.Equal(t, "Signed up at the fair", history.Data[1].Note)
+}
+
+func TestContactDoubleOptInNeedsAMailer(t *testing.T) {
+	records := &consentRecords{orgID: uuid.New(), contactID: uuid.New(), consents: map[types.ContactConsentChannel]types.ContactConsent{}}
+	router, _ := consentRouter(records, nil)
+	path := "/api/v2/contacts/" + records.contactID.String() + "/communication-preferences"
+
+	w := serveAs(router, records.orgID, uuid.New(), http.MethodPut, path, `{"channel": "email", "status": "opted_in", "double_opt_in": true}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+	assert.Empty(t, records.events)
+
+	w = serveAs(router, uuid.New(), uuid.New(), http.MethodPut, path, `{"channel": "email", "status": "opted_in"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestContactUnsubscribesFromTheirLink(t *testing.T) {
+	records := &consentRecords{orgID: uuid.New(), contactID: uuid.New(), consents: map[types.ContactConsentChannel]types.ContactConsent{}}
+	router, svc := consentRouter(records, nil)
+	path := "/api/v2/contacts/" + records.contactID.String() + "/communication-preferences"
+
+	w := serveAs(router, records.orgID, uuid.New(), http.MethodPut, path, `{"channel": "sms", "status": "opted_in"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serveAs(router, records.orgID, uuid.New(), http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var preferences types.ContactPreferences
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preferences))
+	require.Len(t, preferences.Channels, 3)
+	assert.Equal(t, types.ContactConsentUnknown, preferences.Channels[0].Status)
+	assert.Equal(t, types.ContactConsentOptedIn, preferences.Channels[1].Status)
+	assert.Regexp(t, `^/public/v1/unsubscribe/[0-9a-f]{48}$`, preferences.UnsubscribePath)
+
+	w = servePublic(router, http.MethodGet, "/public/v1/unsubscribe/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	// Unsubscribing is from email unless another channel is given, and only
+	// recorded once
+	for range 2 {
+		w = servePublic(router, http.MethodPost, preferences.UnsubscribePath)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"channel": "email", "status": "opted_out"}`, w.Body.String())
+	}
+	w = servePublic(router, http.MethodPost, preferences.UnsubscribePath+"?channel=fax")
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = servePublic(router, http.MethodPost, preferences.UnsubscribePath+"?channel=sms")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = servePublic(router, http.MethodGet, preferences.UnsubscribePath)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"channels": [
+		{"channel": "email", "status": "opted_out"},
+		{"channel": "sms", "status": "opted_out"},
+		{"channel": "phone", "status": "unknown"}
+	]}`, w.Body.String())
+
+	for _, channel := range []string{"email", "sms"} {
+		allowed, err := svc.Allows(context.Background(), records.orgID, records.contactID, channel)
+		require.NoError(t, err)
+		assert.False(t, allowed, channel)
+	}
+	allowed, err := svc.Allows(context.Background(), records.orgID, records.contactID, "phone")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.Len(t, records.events, 3)
+	assert.Equal(t, types.ContactConsentSourceUnsubscribe, records.events[0].Source)
+	assert.Equal(t, types.ContactConsentOptedIn, records.events[0].PreviousStatus)
+	assert.Nil(t, records.events[0].ActorID)
+}
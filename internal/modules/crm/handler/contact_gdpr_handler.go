@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ExportContactData handles POST /api/v2/contacts/:id/gdpr-export, returning
+// a zip archive of all the data held about the contact. The export is
+// recorded and the archive's SHA-256 is sent in X-Content-SHA256.
+func (h *ContactHandlerV2) ExportContactData(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	archive, err := h.gdprService.Export(r.Context(), contactID)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archive.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive.Data)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-SHA256", archive.Request.SHA256)
+	w.Header().Set("X-GDPR-Request-ID", archive.Request.ID.String())
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Data)
+}
+
+// RequestAnonymization handles POST /api/v2/contacts/:id/gdpr-anonymize,
+// requesting the contact's anonymization with a reason. Nothing changes
+// until another user approves it.
+func (h *ContactHandlerV2) RequestAnonymization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ContactAnonymizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.gdprService.RequestAnonymization(r.Context(), contactID, req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusAccepted, request)
+}
+
+// ApproveAnonymization handles POST
+// /api/v2/contacts/:id/gdpr-anonymize/approve, irreversibly anonymizing the
+// contact of the pending request
+func (h *ContactHandlerV2) ApproveAnonymization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewAnonymization(w, r, ps, true)
+}
+
+// RejectAnonymization handles POST
+// /api/v2/contacts/:id/gdpr-anonymize/reject, closing the pending request
+// with a note
+func (h *ContactHandlerV2) RejectAnonymization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewAnonymization(w, r, ps, false)
+}
+
+func (h *ContactHandlerV2) reviewAnonymization(w http.ResponseWriter, r *http.Request, ps httprouter.Params, approve bool) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var review types.ContactGDPRReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request *types.ContactGDPRRequest
+	if approve {
+		request, err = h.gdprService.ApproveAnonymization(r.Context(), contactID, review)
+	} else {
+		request, err = h.gdprService.RejectAnonymization(r.Context(), contactID, review)
+	}
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, request)
+}
+
+// ListGDPRRequests handles GET /api/v2/contacts/:id/gdpr-requests, the
+// audit record of the contact's exports and anonymization requests
+func (h *ContactHandlerV2) ListGDPRRequests(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.gdprService.ListRequests(r.Context(), contactID)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data": requests,
+	})
+}
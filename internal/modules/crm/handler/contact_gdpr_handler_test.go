@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+)
+
+// contextCaller is the user of the request's auth context, with every
+// permission
+type contextCaller struct{}
+
+func (contextCaller) CheckPermission(context.Context, string) error { return nil }
+
+func (contextCaller) GetOrganizationID(ctx context.Context) (uuid.UUID, error) {
+	ac, err := auth.FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return ac.OrganizationID, nil
+}
+
+func (contextCaller) GetUserID(ctx context.Context) (uuid.UUID, error) {
+	ac, err := auth.FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return ac.UserID, nil
+}
+
+// gdprRecords keeps GDPR requests in memory and serves one lead per contact
+type gdprRecords struct {
+	requests     []*types.ContactGDPRRequest
+	anonymizedAt *time.Time
+}
+
+func (g *gdprRecords) ExportSections(_ context.Context, _, contactID uuid.UUID) ([]types.ContactGDPRSection, error) {
+	sections := make([]types.ContactGDPRSection, 0, len(types.ContactGDPRSections))
+	for _, name := range types.ContactGDPRSections {
+		section := types.ContactGDPRSection{Name: name, Data: json.RawMessage(`[]`)}
+		switch name {
+		case "contact":
+			section.Rows, section.Data = 1, json.RawMessage(`[{"id":"`+contactID.String()+`","name":"Ada Lovelace"}]`)
+		case "leads":
+			section.Rows, section.Data = 1, json.RawMessage(`[{"name":"Analytical engine"}]`)
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func (g *gdprRecords) AnonymizedAt(context.Context, uuid.UUID, uuid.UUID) (*time.Time, error) {
+	return g.anonymizedAt, nil
+}
+
+func (g *gdprRecords) CreateRequest(_ context.Context, req *types.ContactGDPRRequest) error {
+	if req.Kind == types.ContactGDPRKindAnonymize {
+		if _, err := g.PendingAnonymization(context.Background(), req.OrganizationID, req.ContactID); err == nil {
+			return types.ErrContactGDPRRequestPending
+		}
+	}
+	req.RequestedAt = time.Now()
+	g.requests = append(g.requests, req)
+	return nil
+}
+
+func (g *gdprRecords) PendingAnonymization(_ context.Context, _, contactID uuid.UUID) (*types.ContactGDPRRequest, error) {
+	for _, req := range g.requests {
+		if req.ContactID == contactID && req.Kind == types.ContactGDPRKindAnonymize && req.Status == types.ContactGDPRStatusPending {
+			return req, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (g *gdprRecords) ListRequests(context.Context, uuid.UUID, uuid.UUID) ([]types.ContactGDPRRequest, error) {
+	requests := make([]types.ContactGDPRRequest, 0, len(g.requests))
+	for _, req := range g.requests {
+		requests = append(requests, *req)
+	}
+	return requests, nil
+}
+
+func (g *gdprRecords) review(requestID, reviewedBy uuid.UUID, status, note string) *types.ContactGDPRRequest {
+	for _, req := range g.requests {
+		if req.ID == requestID {
+			now := time.Now()
+			req.Status, req.ReviewedBy, req.ReviewedAt, req.ReviewNote = status, &reviewedBy, &now, note
+			return req
+		}
+	}
+	return nil
+}
+
+func (g *gdprRecords) Anonymize(_ context.Context, _, requestID, reviewedBy uuid.UUID, note string) (*types.ContactGDPRRequest, error) {
+	now := time.Now()
+	g.anonymizedAt = &now
+	req := g.review(requestID, reviewedBy, types.ContactGDPRStatusCompleted, note)
+	req.CompletedAt, req.Summary = &now, map[string]int64{"contact": 1, "leads": 1}
+	return req, nil
+}
+
+func (g *gdprRecords) Reject(_ context.Context, _, requestID, reviewedBy uuid.UUID, note string) (*types.ContactGDPRRequest, error) {
+	return g.review(requestID, reviewedBy, types.ContactGDPRStatusRejected, note), nil
+}
+
+func gdprRouter(records *gdprRecords) *httprouter.Router {
+	router := httprouter.New()
+	NewContactHandlerV2(nil, nil, service.NewContactGDPRService(records, contextCaller{}, nil)).RegisterRoutes(router)
+	return router
+}
+
+func serveAs(router http.Handler, orgID, userID uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: orgID, UserID: userID}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestContactGDPRExportIsAnArchiveOfEverySection(t *testing.T) {
+	records := &gdprRecords{}
+	orgID, userID, contactID := uuid.New(), uuid.New(), uuid.New()
+
+	w := serveAs(gdprRouter(records), orgID, userID, http.MethodPost, "/api/v2/contacts/"+contactID.String()+"/gdpr-export", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	sum := sha256.Sum256(w.Body.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), w.Header().Get("X-Content-SHA256"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	require.Len(t, files, len(types.ContactGDPRSections)+1)
+
+	var manifest types.ContactGDPRManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, contactID, manifest.ContactID)
+	assert.Equal(t, userID, manifest.ExportedBy)
+	require.Len(t, manifest.Files, len(types.ContactGDPRSections))
+	assert.Equal(t, types.ContactGDPRManifestFile{Path: "leads.json", Section: "leads", Rows: 1}, manifest.Files[2])
+	assert.Contains(t, string(files["contact.json"]), "Ada Lovelace")
+
+	// The export is recorded with the archive's checksum
+	require.Len(t, records.requests, 1)
+	recorded := records.requests[0]
+	assert.Equal(t, manifest.RequestID, recorded.ID)
+	assert.Equal(t, types.ContactGDPRKindExport, recorded.Kind)
+	assert.Equal(t, types.ContactGDPRStatusCompleted, recorded.Status)
+	assert.Equal(t, w.Header().Get("X-Content-SHA256"), recorded.SHA256)
+	assert.Equal(t, int64(1), recorded.Summary["leads"])
+}
+
+func TestContactAnonymizationNeedsAnotherUsersApproval(t *testing.T) {
+	records := &gdprRecords{}
+	router := gdprRouter(records)
+	orgID, requester, reviewer, contactID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	path := "/api/v2/contacts/" + contactID.String() + "/gdpr-anonymize"
+
+	w := serveAs(router, orgID, requester, http.MethodPost, path, `{"reason": ""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serveAs(router, orgID, requester, http.MethodPost, path, `{"reason": "Erasure request by email"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	w = serveAs(router, orgID, requester, http.MethodPost, path, `{"reason": "Erasure request by email"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	// The requester cannot approve their own request, and rejections need a note
+	w = serveAs(router, orgID, requester, http.MethodPost, path+"/approve", `{}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = serveAs(router, orgID, reviewer, http.MethodPost, path+"/reject", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Nil(t, records.anonymizedAt)
+
+	w = serveAs(router, orgID, reviewer, http.MethodPost, path+"/approve", `{"note": "Identity verified"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var approved types.ContactGDPRRequest
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&approved))
+	assert.Equal(t, types.ContactGDPRStatusCompleted, approved.Status)
+	assert.Equal(t, requester, approved.RequestedBy)
+	require.NotNil(t, approved.ReviewedBy)
+	assert.Equal(t, reviewer, *approved.ReviewedBy)
+	assert.NotNil(t, records.anonymizedAt)
+
+	// An anonymized contact cannot be requested again
+	w = serveAs(router, orgID, requester, http.MethodPost, path, `{"reason": "Again"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	w = serveAs(router, orgID, reviewer, http.MethodGet, "/api/v2/contacts/"+contactID.String()+"/gdpr-requests", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var listed struct {
+		Data []types.ContactGDPRRequest `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, "Erasure request by email", listed.Data[0].Reason)
+}
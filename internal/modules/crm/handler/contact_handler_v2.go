@@ -0,0 +1,533 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/apiversion"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	crmerrors "github.com/KevTiv/alieze-erp/pkg/crm/errors"
+	"github.com/KevTiv/alieze-erp/pkg/crm/validation"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// contactsPath is the versioned contact collection
+	contactsPath = "/api/v2/contacts"
+	// defaultContactPageSize is the number of contacts listed when no limit is given
+	defaultContactPageSize = 50
+	// maxContactPageSize bounds the number of contacts and relationships listed at once
+	maxContactPageSize = 100
+)
+
+// ContactHandlerV2 serves contacts under /api/v2/contacts: CRUD, bulk
+// creation, advanced search, relationships, segments, scoring, duplicate
+// merging, GDPR exports and anonymization, and the CRM dashboards.
+// Contacts are always scoped to the caller's organization.
+type ContactHandlerV2 struct {
+	service      *service.ContactServiceV2
+	mergeService *service.ContactMergeService
+	gdprService  *service.ContactGDPRService
+	versions     *apiversion.Catalog
+}
+
+func NewContactHandlerV2(service *service.ContactServiceV2, mergeService *service.ContactMergeService, gdprService *service.ContactGDPRService) *ContactHandlerV2 {
+	return &ContactHandlerV2{service: service, mergeService: mergeService, gdprService: gdprService}
+}
+
+// SetAPIVersions lists the contact routes in the catalog of versioned
+// endpoints when they are registered
+func (h *ContactHandlerV2) SetAPIVersions(catalog *apiversion.Catalog) {
+	h.versions = catalog
+}
+
+// RegisterRoutes registers contact routes. The dashboards, score weights,
+// bulk creation and search sit beside /api/v2/contacts/:id and are told
+// apart from contact IDs by routeBySegment.
+func (h *ContactHandlerV2) RegisterRoutes(r *httprouter.Router) {
+	router := apiversion.NewRouter(r, h.versions)
+	views := map[string]httprouter.Handle{
+		"dashboard":          h.GetCRMDashboard,
+		"activity-dashboard": h.GetActivityDashboard,
+		"score-weights":      h.GetScoreWeights,
+	}
+	actions := map[string]httprouter.Handle{
+		"bulk":   h.BulkCreateContacts,
+		"search": h.AdvancedSearchContacts,
+	}
+
+	router.GET(contactsPath, h.ListContacts)
+	router.POST(contactsPath, h.CreateContact)
+	router.GET(contactsPath+"/:id", routeBySegment("id", views, h.GetContact))
+	router.POST(contactsPath+"/:id", routeBySegment("id", actions, nil))
+	router.PUT(contactsPath+"/:id", routeBySegment("id", map[string]httprouter.Handle{
+		"score-weights": h.UpdateScoreWeights,
+	}, h.UpdateContact))
+	router.DELETE(contactsPath+"/:id", h.DeleteContact)
+
+	router.GET(contactsPath+"/:id/relationships", h.ListRelationships)
+	router.POST(contactsPath+"/:id/relationships", h.CreateRelationship)
+	router.POST(contactsPath+"/:id/segments", h.AddToSegments)
+	router.GET(contactsPath+"/:id/score", h.GetContactScore)
+	router.GET(contactsPath+"/:id/score/history", h.GetContactScoreHistory)
+	router.GET(contactsPath+"/:id/duplicates", h.FindDuplicates)
+	router.POST(contactsPath+"/:id/merge", h.MergeContact)
+	router.POST(contactsPath+"/:id/gdpr-export", h.ExportContactData)
+	router.POST(contactsPath+"/:id/gdpr-anonymize", h.RequestAnonymization)
+	router.POST(contactsPath+"/:id/gdpr-anonymize/approve", h.ApproveAnonymization)
+	router.POST(contactsPath+"/:id/gdpr-anonymize/reject", h.RejectAnonymization)
+	router.GET(contactsPath+"/:id/gdpr-requests", h.ListGDPRRequests)
+}
+
+// ListContacts handles GET /api/v2/contacts with optional name, email,
+// phone, match_mode, is_customer, is_vendor, limit and offset filters
+func (h *ContactHandlerV2) ListContacts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.ContactFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Limit:          defaultContactPageSize,
+	}
+
+	matchMode, err := database.ParseMatchMode(q.Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
+
+	if v := q.Get("name"); v != "" {
+		filter.Name = &v
+	}
+	if v := q.Get("email"); v != "" {
+		filter.Email = &v
+	}
+	if v := q.Get("phone"); v != "" {
+		filter.Phone = &v
+	}
+	if v := q.Get("is_customer"); v != "" {
+		isCustomer, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid is_customer value", http.StatusBadRequest)
+			return
+		}
+		filter.IsCustomer = &isCustomer
+	}
+	if v := q.Get("is_vendor"); v != "" {
+		isVendor, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid is_vendor value", http.StatusBadRequest)
+			return
+		}
+		filter.IsVendor = &isVendor
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(limit, maxContactPageSize)
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		filter.Offset = offset
+	}
+
+	contacts, total, err := h.service.ListContacts(r.Context(), filter)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   nonNilContacts(contacts),
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// CreateContact handles POST /api/v2/contacts
+func (h *ContactHandlerV2) CreateContact(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.OrganizationID = authCtx.OrganizationID
+
+	contact, err := h.service.CreateContact(r.Context(), req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusCreated, contact)
+}
+
+// GetContact handles GET /api/v2/contacts/:id
+func (h *ContactHandlerV2) GetContact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.service.GetContact(r.Context(), id)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, contact)
+}
+
+// UpdateContact handles PUT /api/v2/contacts/:id
+func (h *ContactHandlerV2) UpdateContact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req service.ContactUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.service.UpdateContact(r.Context(), id, req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, contact)
+}
+
+// DeleteContact handles DELETE /api/v2/contacts/:id
+func (h *ContactHandlerV2) DeleteContact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteContact(r.Context(), id); err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkCreateContacts handles POST /api/v2/contacts/bulk. Contacts that
+// fail validation are reported in errors while the others are created.
+func (h *ContactHandlerV2) BulkCreateContacts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var reqs []service.ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(reqs) == 0 {
+		http.Error(w, "At least one contact is required", http.StatusBadRequest)
+		return
+	}
+	for i := range reqs {
+		reqs[i].OrganizationID = authCtx.OrganizationID
+	}
+
+	created, errs := h.service.BulkCreateContacts(r.Context(), reqs)
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	writeContactJSON(w, http.StatusCreated, map[string]interface{}{
+		"created": nonNilContacts(created),
+		"errors":  messages,
+	})
+}
+
+// AdvancedSearchContacts handles POST /api/v2/contacts/search
+func (h *ContactHandlerV2) AdvancedSearchContacts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var filter types.AdvancedContactFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.OrganizationID = authCtx.OrganizationID
+	filter.Paginate()
+
+	contacts, total, err := h.service.AdvancedSearchContacts(r.Context(), filter)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data":     nonNilContacts(contacts),
+		"total":    total,
+		"page":     filter.Page,
+		"pageSize": filter.PageSize,
+	})
+}
+
+// ListRelationships handles GET /api/v2/contacts/:id/relationships with
+// optional type and limit filters
+func (h *ContactHandlerV2) ListRelationships(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultContactPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxContactPageSize)
+	}
+
+	relationships, err := h.service.ListRelationships(r.Context(), authCtx.OrganizationID, contactID, r.URL.Query().Get("type"), limit)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+	if relationships == nil {
+		relationships = []*types.ContactRelationship{}
+	}
+
+	writeContactJSON(w, http.StatusOK, relationships)
+}
+
+// CreateRelationship handles POST /api/v2/contacts/:id/relationships
+func (h *ContactHandlerV2) CreateRelationship(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ContactRelationshipCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RelatedContactID == uuid.Nil {
+		http.Error(w, "related_contact_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.RelatedContactID == contactID {
+		http.Error(w, "A contact cannot be related to itself", http.StatusBadRequest)
+		return
+	}
+
+	relationship, err := h.service.CreateRelationship(r.Context(), authCtx.OrganizationID, contactID, req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusCreated, relationship)
+}
+
+// AddToSegments handles POST /api/v2/contacts/:id/segments, adding the
+// contact to segments and tagging it
+func (h *ContactHandlerV2) AddToSegments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ContactSegmentationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.SegmentIDs) == 0 && len(req.CustomTags) == 0 {
+		http.Error(w, "segment_ids or custom_tags is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.AddToSegments(r.Context(), authCtx.OrganizationID, contactID, req); err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetContactScore handles GET /api/v2/contacts/:id/score
+func (h *ContactHandlerV2) GetContactScore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	score, err := h.service.CalculateContactScore(r.Context(), authCtx.OrganizationID, contactID)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, score)
+}
+
+// GetCRMDashboard handles GET /api/v2/contacts/dashboard. time_range
+// defaults to 30d.
+func (h *ContactHandlerV2) GetCRMDashboard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	timeRange := r.URL.Query().Get("time_range")
+	if timeRange == "" {
+		timeRange = "30d"
+	}
+
+	dashboard, err := h.service.GetCRMDashboard(r.Context(), authCtx.OrganizationID, timeRange)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, dashboard)
+}
+
+// GetActivityDashboard handles GET /api/v2/contacts/activity-dashboard.
+// contact_type is all, customers, vendors or leads and defaults to all;
+// time_range defaults to 30d.
+func (h *ContactHandlerV2) GetActivityDashboard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	contactType := q.Get("contact_type")
+	if contactType == "" {
+		contactType = "all"
+	}
+	timeRange := q.Get("time_range")
+	if timeRange == "" {
+		timeRange = "30d"
+	}
+
+	dashboard, err := h.service.GetActivityDashboard(r.Context(), authCtx.OrganizationID, contactType, timeRange)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, dashboard)
+}
+
+func nonNilContacts(contacts []*types.Contact) []*types.Contact {
+	if contacts == nil {
+		return []*types.Contact{}
+	}
+	return contacts
+}
+
+func writeContactJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeContactError answers with the status the contact service's error
+// carries. Missing contacts surface as sql.ErrNoRows from the repository
+// or as the service's contact_not_found error.
+func writeContactError(w http.ResponseWriter, err error) {
+	var validationErrs validation.ValidationErrors
+	var validationErr *validation.ValidationError
+	var crmErr *crmerrors.CRMError
+	switch {
+	case errors.As(err, &validationErrs), errors.As(err, &validationErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &crmErr):
+		switch crmErr.Code {
+		case "contact_not_found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "invalid_relationship_type", "organization_id_required", "INVALID_TIME_RANGE":
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), crmErr.HTTPStatus())
+		}
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	crmerrors "github.com/KevTiv/alieze-erp/pkg/crm/errors"
+	"github.com/KevTiv/alieze-erp/pkg/crm/validation"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactRoutesRegisterWithoutConflicts(t *testing.T) {
+	router := httprouter.New()
+	require.NotPanics(t, func() {
+		NewContactHandlerV2(nil, nil, nil).RegisterRoutes(router)
+		NewActivityHandler(nil).RegisterRoutes(router)
+	})
+
+	id := uuid.NewString()
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v2/contacts"},
+		{http.MethodPost, "/api/v2/contacts"},
+		{http.MethodGet, "/api/v2/contacts/" + id},
+		{http.MethodPut, "/api/v2/contacts/" + id},
+		{http.MethodDelete, "/api/v2/contacts/" + id},
+		{http.MethodGet, "/api/v2/contacts/dashboard"},
+		{http.MethodGet, "/api/v2/contacts/activity-dashboard"},
+		{http.MethodPost, "/api/v2/contacts/bulk"},
+		{http.MethodPost, "/api/v2/contacts/search"},
+		{http.MethodGet, "/api/v2/contacts/" + id + "/relationships"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/relationships"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/segments"},
+		{http.MethodGet, "/api/v2/contacts/" + id + "/score"},
+		{http.MethodGet, "/api/v2/contacts/" + id + "/score/history"},
+		{http.MethodGet, "/api/v2/contacts/score-weights"},
+		{http.MethodPut, "/api/v2/contacts/score-weights"},
+		{http.MethodGet, "/api/v2/contacts/" + id + "/duplicates"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/merge"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/gdpr-export"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/gdpr-anonymize"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/gdpr-anonymize/approve"},
+		{http.MethodPost, "/api/v2/contacts/" + id + "/gdpr-anonymize/reject"},
+		{http.MethodGet, "/api/v2/contacts/" + id + "/gdpr-requests"},
+	} {
+		handle, _, _ := router.Lookup(route.method, route.path)
+		assert.NotNil(t, handle, route.method+" "+route.path)
+	}
+
+	// Only bulk and search are posted to a contact path
+	handle, ps, _ := router.Lookup(http.MethodPost, "/api/v2/contacts/"+id)
+	require.NotNil(t, handle)
+	w := httptest.NewRecorder()
+	handle(w, httptest.NewRequest(http.MethodPost, "/api/v2/contacts/"+id, nil), ps)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWriteContactError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"validation", validation.ValidationErrors{{Field: "name", Message: "is required"}}, http.StatusBadRequest},
+		{"missing contact", crmerrors.Wrap(fmt.Errorf("contact not found: %w", sql.ErrNoRows), "GET_FAILED", "failed to get contact"), http.StatusNotFound},
+		{"missing related contact", crmerrors.New("contact_not_found", "contact does not exist"), http.StatusNotFound},
+		{"bad relationship type", crmerrors.New("invalid_relationship_type", "invalid relationship type"), http.StatusBadRequest},
+		{"other organization", crmerrors.ErrOrganizationAccess, http.StatusForbidden},
+		{"time range", crmerrors.Wrap(fmt.Errorf("bad range"), "INVALID_TIME_RANGE", "invalid time range"), http.StatusBadRequest},
+		{"database", crmerrors.Wrap(fmt.Errorf("connection reset"), "LIST_FAILED", "failed to list contacts"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeContactError(w, tt.err)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
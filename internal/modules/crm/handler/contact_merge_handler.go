@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// FindDuplicates handles GET /api/v2/contacts/:id/duplicates, listing the
+// contacts sharing the contact's email or phone number or with a similar
+// name. min_score defaults to service.DefaultDuplicateCandidateScore.
+func (h *ContactHandlerV2) FindDuplicates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	minScore := service.DefaultDuplicateCandidateScore
+	if v := q.Get("min_score"); v != "" {
+		minScore, err = strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid min_score", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	matches, err := h.mergeService.FindDuplicates(r.Context(), contactID, minScore, limit)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+	if matches == nil {
+		matches = []*types.ContactDuplicateMatch{}
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data": matches,
+	})
+}
+
+// MergeContact handles POST /api/v2/contacts/:id/merge, merging
+// duplicate_contact_id into the contact. With dry_run the merge is only
+// previewed.
+func (h *ContactHandlerV2) MergeContact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.MergeContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.mergeService.MergeContacts(r.Context(), contactID, req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, result)
+}
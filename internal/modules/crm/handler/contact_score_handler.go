@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetContactScoreHistory handles GET /api/v2/contacts/:id/score/history,
+// the contact's daily scores. from and to are dates (YYYY-MM-DD); the last
+// 90 days are returned by default.
+func (h *ContactHandlerV2) GetContactScoreHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	var from, to *time.Time
+	for _, param := range []struct {
+		name  string
+		value **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := r.URL.Query().Get(param.name)
+		if v == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			http.Error(w, "Invalid "+param.name+" date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*param.value = &date
+	}
+
+	history, err := h.service.ContactScoreHistory(r.Context(), authCtx.OrganizationID, contactID, from, to)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data": history,
+	})
+}
+
+// GetScoreWeights handles GET /api/v2/contacts/score-weights
+func (h *ContactHandlerV2) GetScoreWeights(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	weights, err := h.service.GetScoreWeights(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, weights)
+}
+
+// UpdateScoreWeights handles PUT /api/v2/contacts/score-weights
+func (h *ContactHandlerV2) UpdateScoreWeights(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ContactScoreWeightsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	weights, err := h.service.UpdateScoreWeights(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, weights)
+}
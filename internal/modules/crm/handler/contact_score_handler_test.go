@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authtypes "github.com/KevTiv/alieze-erp/internal/modules/auth/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/crm/base"
+)
+
+// orgMember is a user of one organization with every permission
+type orgMember struct {
+	orgID, userID uuid.UUID
+}
+
+func (a orgMember) CheckOrganizationAccess(_ context.Context, orgID uuid.UUID) error {
+	if orgID != a.orgID {
+		return assert.AnError
+	}
+	return nil
+}
+
+func (a orgMember) CheckUserPermission(ctx context.Context, _, orgID uuid.UUID, _ string) error {
+	return a.CheckOrganizationAccess(ctx, orgID)
+}
+
+func (a orgMember) GetCurrentUser(context.Context) (*authtypes.User, error) {
+	return &authtypes.User{ID: a.userID}, nil
+}
+
+// existingContacts reports every contact as existing
+type existingContacts struct {
+	base.Repository[types.Contact, types.ContactFilter]
+}
+
+func (existingContacts) ContactExists(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+// recordedEngagement serves fixed signals and keeps saved weights
+type recordedEngagement struct {
+	types.ContactScoreRepository
+	signals types.ContactEngagementSignals
+	weights *types.ContactScoreWeights
+}
+
+func (r *recordedEngagement) Weights(_ context.Context, orgID uuid.UUID) (*types.ContactScoreWeights, error) {
+	if r.weights != nil {
+		return r.weights, nil
+	}
+	defaults := types.DefaultContactScoreWeights(orgID)
+	return &defaults, nil
+}
+
+func (r *recordedEngagement) SaveWeights(_ context.Context, weights types.ContactScoreWeights) (*types.ContactScoreWeights, error) {
+	r.weights = &weights
+	return &weights, nil
+}
+
+func (r *recordedEngagement) Signals(_ context.Context, orgID, contactID uuid.UUID, at time.Time, _ time.Duration) (*types.ContactEngagementSignals, error) {
+	signals := r.signals
+	signals.ContactID, signals.OrganizationID, signals.At = contactID, orgID, at
+	lastActivity := at.Add(-9 * 24 * time.Hour)
+	signals.LastActivityAt = &lastActivity
+	return &signals, nil
+}
+
+func TestContactScoreWeighsEngagementSignals(t *testing.T) {
+	member := orgMember{orgID: uuid.New(), userID: uuid.New()}
+	scores := &recordedEngagement{signals: types.ContactEngagementSignals{
+		Activities: 6, EmailsSent: 4, EmailsReceived: 3, WonDeals: 1, OpenLeadProbability: 70,
+	}}
+	contacts := service.NewContactServiceV2(existingContacts{}, member, base.ServiceOptions{})
+	contacts.SetScoring(scores)
+	router := httprouter.New()
+	NewContactHandlerV2(contacts, nil, nil).RegisterRoutes(router)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: member.orgID, UserID: member.userID}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	score := func() types.ContactScore {
+		w := serve(http.MethodGet, "/api/v2/contacts/"+uuid.NewString()+"/score", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var score types.ContactScore
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&score))
+		return score
+	}
+
+	// Recency 90, frequency 50, response rate 75 and won deals 33 with the
+	// default 30/30/20/20 weights
+	got := score()
+	assert.Equal(t, 64, got.EngagementScore)
+	assert.Equal(t, 70, got.LeadScore)
+
+	w := serve(http.MethodPut, "/api/v2/contacts/score-weights", `{"recency": 0, "frequency": 0, "response_rate": 0, "won_deals": 0}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = serve(http.MethodPut, "/api/v2/contacts/score-weights", `{"recency": 50, "frequency": 0, "response_rate": 50, "won_deals": 0}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, scores.weights.UpdatedBy)
+	assert.Equal(t, member.userID, *scores.weights.UpdatedBy)
+	assert.Equal(t, 83, score().EngagementScore)
+
+	// Without sent emails the response rate is left out
+	scores.signals.EmailsSent, scores.signals.EmailsReceived = 0, 0
+	assert.Equal(t, 90, score().EngagementScore)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/crm/base"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+// searchedContacts records the filter searched with and renders its query
+type searchedContacts struct {
+	filter types.AdvancedContactFilter
+	where  string
+	args   []interface{}
+}
+
+func (s *searchedContacts) Search(_ context.Context, filter types.AdvancedContactFilter) ([]*types.Contact, error) {
+	s.filter = filter
+	if filter.Condition != nil {
+		s.where, s.args = filter.Condition.Render(database.Postgres, nil)
+	}
+	return []*types.Contact{{ID: uuid.New(), OrganizationID: filter.OrganizationID, Name: "Ada Lovelace"}}, nil
+}
+
+func (s *searchedContacts) Count(context.Context, types.AdvancedContactFilter) (int, error) {
+	return 41, nil
+}
+
+// contactFields defines the contact custom fields of every organization
+type contactFields []customfields.Definition
+
+func (f contactFields) Definitions(_ context.Context, _ uuid.UUID, entity string) ([]customfields.Definition, error) {
+	if entity != "contact" {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func TestAdvancedSearchContactsWithQuery(t *testing.T) {
+	member := orgMember{orgID: uuid.New(), userID: uuid.New()}
+	search := &searchedContacts{}
+	contacts := service.NewContactServiceV2(existingContacts{}, member, base.ServiceOptions{})
+	contacts.SetSearch(search, contactFields{
+		{Entity: "contact", Name: "region", Label: "Region", Type: customfields.TypeSelect, Options: []string{"emea", "apac"}},
+	})
+	router := httprouter.New()
+	NewContactHandlerV2(contacts, nil, nil).RegisterRoutes(router)
+	tagID := uuid.New()
+
+	w := serveAs(router, member.orgID, member.userID, http.MethodPost, "/api/v2/contacts/search", `{
+		"search_query": "ada",
+		"query": {"and": [
+			{"field": "custom.region", "op": "in", "value": ["emea", "apac"]},
+			{"or": [
+				{"field": "is_customer", "op": "eq", "value": true},
+				{"field": "tags", "op": "has", "value": "`+tagID.String()+`"}
+			]}
+		]}
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var page struct {
+		Data     []types.Contact `json:"data"`
+		Total    int             `json:"total"`
+		Page     int             `json:"page"`
+		PageSize int             `json:"pageSize"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, 41, page.Total)
+	assert.Equal(t, 1, page.Page)
+	assert.Equal(t, 20, page.PageSize)
+
+	assert.Equal(t, member.orgID, search.filter.OrganizationID)
+	assert.Equal(t, 20, search.filter.PageSize)
+	assert.Equal(t, `((custom_fields->>'region') IN ($1, $2) AND (is_customer = $3 OR ($4 = ANY(tags))))`, search.where)
+	assert.Equal(t, []interface{}{"emea", "apac", true, tagID}, search.args)
+
+	for _, body := range []string{
+		`{"query": {"field": "password_hash", "op": "eq", "value": "x"}}`,
+		`{"query": {"field": "custom.region", "op": "eq", "value": "mars"}}`,
+		`{"query": {"or": []}}`,
+		`{"tags": ["vip"]}`,
+	} {
+		w := serveAs(router, member.orgID, member.userID, http.MethodPost, "/api/v2/contacts/search", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
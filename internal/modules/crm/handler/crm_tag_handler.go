@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type CRMTagHandler struct {
+	service *service.CRMTagService
+}
+
+func NewCRMTagHandler(service *service.CRMTagService) *CRMTagHandler {
+	return &CRMTagHandler{
+		service: service,
+	}
+}
+
+func (h *CRMTagHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/crm/tags", h.ListTags)
+	router.POST("/api/crm/tags", h.CreateTag)
+	router.GET("/api/crm/tags/:id", h.GetTag)
+	router.PUT("/api/crm/tags/:id", h.UpdateTag)
+	router.DELETE("/api/crm/tags/:id", h.DeleteTag)
+
+	router.POST(leadsPath+"/:id/tags", h.addTags(types.CRMTagTargetLeads))
+	router.DELETE(leadsPath+"/:id/tags/:tag_id", h.removeTag(types.CRMTagTargetLeads))
+	router.POST(contactsPath+"/:id/tags", h.addTags(types.CRMTagTargetContacts))
+	router.DELETE(contactsPath+"/:id/tags/:tag_id", h.removeTag(types.CRMTagTargetContacts))
+}
+
+// ListTags handles GET /api/crm/tags?name=&limit=&offset=
+func (h *CRMTagHandler) ListTags(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.CRMTagFilter{OrganizationID: authCtx.OrganizationID}
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = l
+		}
+	}
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil {
+			filter.Offset = o
+		}
+	}
+
+	tags, err := h.service.ListTags(r.Context(), filter)
+	if err != nil {
+		writeCRMTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *CRMTagHandler) CreateTag(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CRMTagCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.CreateTag(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeCRMTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *CRMTagHandler) GetTag(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.GetTag(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeCRMTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *CRMTagHandler) UpdateTag(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CRMTagUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.UpdateTag(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeCRMTagError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *CRMTagHandler) DeleteTag(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTag(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeCRMTagError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addTags handles POST /<records>/:id/tags with {"tag_ids": [...]}
+func (h *CRMTagHandler) addTags(target types.CRMTagTarget) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		authCtx, ok := auth.RequireAuthContext(w, r)
+		if !ok {
+			return
+		}
+
+		recordID, err := uuid.Parse(ps.ByName("id"))
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		var req types.CRMTagAssignment
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tagIDs, err := h.service.AddTags(r.Context(), authCtx.OrganizationID, target, recordID, req.TagIDs)
+		if err != nil {
+			writeCRMTagError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.CRMTagAssignment{TagIDs: tagIDs})
+	}
+}
+
+// removeTag handles DELETE /<records>/:id/tags/:tag_id
+func (h *CRMTagHandler) removeTag(target types.CRMTagTarget) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		authCtx, ok := auth.RequireAuthContext(w, r)
+		if !ok {
+			return
+		}
+
+		recordID, err := uuid.Parse(ps.ByName("id"))
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		tagID, err := uuid.Parse(ps.ByName("tag_id"))
+		if err != nil {
+			http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+
+		tagIDs, err := h.service.RemoveTag(r.Context(), authCtx.OrganizationID, target, recordID, tagID)
+		if err != nil {
+			writeCRMTagError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.CRMTagAssignment{TagIDs: tagIDs})
+	}
+}
+
+func writeCRMTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidCRMTag):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrCRMTagExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrCRMTagNotFound), errors.Is(err, types.ErrTaggedRecordNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/pkg/attachments"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// attachmentFormOverhead is the room left in upload bodies for the
+// multipart headers and boundaries around the file
+const attachmentFormOverhead = 1 << 20
+
+// UploadLeadAttachment handles POST /api/v1/leads/:id/attachments, a
+// multipart form with the file in its "file" field. The file is streamed to
+// storage rather than buffered.
+func (h *LeadHandler) UploadLeadAttachment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.leadService.AttachmentPolicy().MaxSize+attachmentFormOverhead)
+	form, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart form with a file field", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeLeadAttachmentError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.leadService.UploadLeadAttachment(r.Context(), authCtx.OrganizationID, authCtx.UserID, leadID, service.LeadAttachmentUpload{
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        -1,
+			Reader:      part,
+		})
+		part.Close()
+		if err != nil {
+			writeLeadAttachmentError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+		return
+	}
+}
+
+// ListLeadAttachments handles GET /api/v1/leads/:id/attachments
+func (h *LeadHandler) ListLeadAttachments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.leadService.ListLeadAttachments(r.Context(), authCtx.OrganizationID, leadID)
+	if err != nil {
+		writeLeadAttachmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetLeadAttachment handles GET /api/v1/leads/:id/attachments/:attachment_id.
+// With ?download=true it redirects to the signed download URL.
+func (h *LeadHandler) GetLeadAttachment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := uuid.Parse(ps.ByName("attachment_id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.leadService.GetLeadAttachment(r.Context(), authCtx.OrganizationID, leadID, attachmentID)
+	if err != nil {
+		writeLeadAttachmentError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("download") == "true" && attachment.DownloadURL != "" {
+		http.Redirect(w, r, attachment.DownloadURL, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DeleteLeadAttachment handles DELETE /api/v1/leads/:id/attachments/:attachment_id
+func (h *LeadHandler) DeleteLeadAttachment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := uuid.Parse(ps.ByName("attachment_id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leadService.DeleteLeadAttachment(r.Context(), authCtx.OrganizationID, leadID, attachmentID); err != nil {
+		writeLeadAttachmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeLeadAttachmentError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, attachments.ErrTooLarge), errors.As(err, &tooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, attachments.ErrTypeNotAllowed):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, attachments.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, attachments.ErrNotFound), errors.Is(err, sql.ErrNoRows),
+		strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, attachments.ErrStorageUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
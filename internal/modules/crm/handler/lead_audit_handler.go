@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetLeadHistory handles retrieval of a lead's field changes, most recent
+// first
+func (h *LeadHandler) GetLeadHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.leadService.GetLeadHistory(r.Context(), orgID, id)
+	if err != nil {
+		writeLeadAuditError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// ExportLeadAudit handles the export of the organization's lead field
+// changes between from and to (YYYY-MM-DD or RFC 3339), optionally for one
+// lead_id, field or changed_by user. With format=csv the entries are sent
+// as a CSV attachment.
+func (h *LeadHandler) ExportLeadAudit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.LeadAuditFilter{OrganizationID: authCtx.OrganizationID}
+	query := r.URL.Query()
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		date, err := parseStageDate(query.Get(param))
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = date
+	}
+	for param, target := range map[string]**uuid.UUID{"lead_id": &filter.LeadID, "changed_by": &filter.ChangedBy} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = &id
+	}
+	if field := query.Get("field"); field != "" {
+		filter.Field = &field
+	}
+
+	entries, err := h.leadService.ExportLeadAudit(r.Context(), filter)
+	if err != nil {
+		writeLeadAuditError(w, err)
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		filename := fmt.Sprintf("lead-audit-%s-%s.csv", filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02"))
+		writeLeadAuditCSV(w, filename, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func writeLeadAuditCSV(w http.ResponseWriter, filename string, entries []types.LeadAuditEntry) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"changed_at", "lead_id", "field", "old_value", "new_value", "source", "changed_by"})
+	for _, e := range entries {
+		changedBy := ""
+		if e.ChangedBy != nil {
+			changedBy = e.ChangedBy.String()
+		}
+		cw.Write([]string{
+			e.ChangedAt.UTC().Format(time.RFC3339),
+			e.LeadID.String(),
+			e.Field,
+			string(e.OldValue),
+			string(e.NewValue),
+			string(e.Source),
+			changedBy,
+		})
+	}
+	cw.Flush()
+}
+
+func writeLeadAuditError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadAuditFilter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
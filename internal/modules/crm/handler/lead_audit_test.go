@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+)
+
+// storedLead keeps one lead across updates
+type storedLead struct {
+	types.LeadRepository
+	lead types.Lead
+}
+
+func (r *storedLead) FindByID(context.Context, uuid.UUID) (*types.Lead, error) {
+	lead := r.lead
+	return &lead, nil
+}
+
+func (r *storedLead) Update(_ context.Context, lead types.Lead) (*types.Lead, error) {
+	r.lead = lead
+	return &lead, nil
+}
+
+// auditLog keeps the recorded entries in memory
+type auditLog struct {
+	types.LeadAuditRepository
+	entries []types.LeadAuditEntry
+	filter  types.LeadAuditFilter
+}
+
+func (r *auditLog) Record(_ context.Context, entries []types.LeadAuditEntry) error {
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func (r *auditLog) FindByLead(context.Context, uuid.UUID, uuid.UUID) ([]types.LeadAuditEntry, error) {
+	return r.entries, nil
+}
+
+func (r *auditLog) Export(_ context.Context, filter types.LeadAuditFilter) ([]types.LeadAuditEntry, error) {
+	r.filter = filter
+	return r.entries, nil
+}
+
+// actingUser allows everything as one user
+type actingUser struct {
+	userID uuid.UUID
+}
+
+func (a actingUser) CheckPermission(context.Context, string) error { return nil }
+
+func (a actingUser) GetOrganizationID(context.Context) (uuid.UUID, error) { return uuid.Nil, nil }
+
+func (a actingUser) GetUserID(context.Context) (uuid.UUID, error) { return a.userID, nil }
+
+func TestLeadUpdatesAreRecordedInHistory(t *testing.T) {
+	orgID, userID, leadID := uuid.New(), uuid.New(), uuid.New()
+	email := "old@example.com"
+	leads := &storedLead{lead: types.Lead{
+		ID: leadID, OrganizationID: orgID, Name: "Acme", Email: &email, Priority: types.LeadPriorityLow,
+	}}
+	history := &auditLog{}
+	svc := service.NewLeadService(leads, actingUser{userID: userID}, nil, nil)
+	svc.SetAudit(history)
+
+	router := httprouter.New()
+	NewLeadHandler(svc).RegisterRoutes(router)
+	serve := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: orgID, UserID: userID}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// The unchanged name is not recorded
+	w := serve(http.MethodPut, "/api/v1/leads/"+leadID.String(),
+		[]byte(`{"name": "Acme", "email": "new@example.com", "priority": "high"}`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serve(http.MethodGet, "/api/v1/leads/"+leadID.String()+"/history", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var entries []types.LeadAuditEntry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "email", entries[0].Field)
+	assert.JSONEq(t, `"old@example.com"`, string(entries[0].OldValue))
+	assert.JSONEq(t, `"new@example.com"`, string(entries[0].NewValue))
+	assert.Equal(t, "priority", entries[1].Field)
+	assert.Equal(t, types.LeadAuditUpdate, entries[1].Source)
+	assert.Equal(t, &userID, entries[1].ChangedBy)
+
+	w = serve(http.MethodGet, "/api/v1/lead-audit-log?from=2025-01-01&to=2025-02-01&field=email&format=csv", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "email", *history.filter.Field)
+	assert.Equal(t, types.MaxLeadAuditExport, history.filter.Limit)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"email", `"old@example.com"`, `"new@example.com"`}, records[1][2:5])
+
+	// An export covers at most a year
+	w = serve(http.MethodGet, "/api/v1/lead-audit-log?from=2023-01-01&to=2025-01-01", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetLeadBoard handles the kanban lead board: the leads grouped by stage,
+// with each column's count and expected revenue and its first limit cards.
+// It takes the filters and sort of GET /api/v1/leads. A column's
+// next_cursor, passed back as cursor, loads its next cards; with stage_id
+// set only that column is returned.
+func (h *LeadHandler) GetLeadBoard(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	q := r.URL.Query()
+	filter, err := parseLeadFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.SortBy = types.LeadSortField(strings.ToLower(q.Get("sort_by")))
+	filter.SortDir = types.SortDirection(strings.ToLower(q.Get("sort_dir")))
+
+	req := types.LeadBoardRequest{Filter: filter, Cursors: q["cursor"]}
+	if limit := q.Get("limit"); limit != "" {
+		val, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		req.Limit = val
+	}
+
+	board, err := h.leadService.GetLeadBoard(r.Context(), orgID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrInvalidLeadBoard), errors.Is(err, types.ErrInvalidLeadSort),
+			errors.Is(err, computed.ErrUnknownField),
+			errors.Is(err, customfields.ErrUnknownField), errors.Is(err, customfields.ErrInvalidValue):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "permission denied"):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(board)
+}
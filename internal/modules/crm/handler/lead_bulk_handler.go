@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxLeadBulkBody bounds a bulk request, enough for MaxLeadBulkSize IDs
+const maxLeadBulkBody = 1 << 20
+
+// BulkUpdateLeads handles applying the same changes to the leads listed by
+// ids or matching a filter. It responds 200 with a result per lead, even
+// when some leads failed.
+func (h *LeadHandler) BulkUpdateLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var req types.LeadBulkUpdateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLeadBulkBody)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.leadService.BulkUpdateLeads(r.Context(), orgID, req)
+	if err != nil {
+		writeLeadBulkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// BulkDeleteLeads handles deleting the leads listed by ids or matching a
+// filter
+func (h *LeadHandler) BulkDeleteLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var req types.LeadBulkDeleteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLeadBulkBody)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.leadService.BulkDeleteLeads(r.Context(), orgID, req)
+	if err != nil {
+		writeLeadBulkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeLeadBulkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadBulkRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListCadences handles cadence listing
+func (h *LeadHandler) ListCadences(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	cadences, err := h.leadService.ListCadences(r.Context(), orgID)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cadences)
+}
+
+// GetCadence handles retrieval of a cadence with its steps
+func (h *LeadHandler) GetCadence(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cadence ID", http.StatusBadRequest)
+		return
+	}
+
+	cadence, err := h.leadService.GetCadence(r.Context(), orgID, id)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cadence)
+}
+
+// CreateCadence handles cadence creation
+func (h *LeadHandler) CreateCadence(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var req types.LeadCadenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	cadence, err := h.leadService.CreateCadence(r.Context(), orgID, req)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cadence)
+}
+
+// UpdateCadence handles cadence updates
+func (h *LeadHandler) UpdateCadence(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cadence ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadCadenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	cadence, err := h.leadService.UpdateCadence(r.Context(), orgID, id, req)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cadence)
+}
+
+// DeleteCadence handles cadence deletion
+func (h *LeadHandler) DeleteCadence(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cadence ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leadService.DeleteCadence(r.Context(), orgID, id); err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCadencePerformance handles retrieval of the per-cadence completion
+// and task counts
+func (h *LeadHandler) GetCadencePerformance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	performance, err := h.leadService.GetCadencePerformance(r.Context(), orgID)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(performance)
+}
+
+// GetLeadCadences handles retrieval of a lead's cadence enrollments and
+// their tasks
+func (h *LeadHandler) GetLeadCadences(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := h.leadService.GetLeadCadences(r.Context(), orgID, id)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollments)
+}
+
+// SkipCadenceTask handles skipping a pending cadence task
+func (h *LeadHandler) SkipCadenceTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cadence task ID", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.leadService.SkipCadenceTask(r.Context(), orgID, id)
+	if err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// StopCadenceEnrollment handles taking a lead out of a cadence
+func (h *LeadHandler) StopCadenceEnrollment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cadence enrollment ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leadService.StopCadenceEnrollment(r.Context(), orgID, id); err != nil {
+		writeLeadCadenceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeLeadCadenceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadCadence):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrLeadCadenceExists), errors.Is(err, types.ErrLeadCadenceClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type LeadCaptureHandler struct {
+	service *service.LeadCaptureService
+}
+
+func NewLeadCaptureHandler(service *service.LeadCaptureService) *LeadCaptureHandler {
+	return &LeadCaptureHandler{
+		service: service,
+	}
+}
+
+func (h *LeadCaptureHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/crm/lead-capture-forms", h.CreateForm)
+	router.GET("/api/crm/lead-capture-forms/:id", h.GetForm)
+	router.GET("/api/crm/lead-capture-forms", h.ListForms)
+	router.PUT("/api/crm/lead-capture-forms/:id", h.UpdateForm)
+	router.DELETE("/api/crm/lead-capture-forms/:id", h.DeleteForm)
+	router.POST("/api/crm/lead-capture-forms/:id/submit", h.Submit)
+}
+
+func (h *LeadCaptureHandler) CreateForm(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.LeadCaptureFormCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CreateForm(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *LeadCaptureHandler) GetForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid form ID", http.StatusBadRequest)
+		return
+	}
+
+	form, err := h.service.GetForm(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(form)
+}
+
+func (h *LeadCaptureHandler) ListForms(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.LeadCaptureFormFilter{}
+
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
+
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = &name
+	}
+
+	if active := r.URL.Query().Get("active"); active != "" {
+		val, err := strconv.ParseBool(active)
+		if err != nil {
+			http.Error(w, "Invalid active value", http.StatusBadRequest)
+			return
+		}
+		filter.Active = &val
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = val
+		}
+	}
+
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		if val, err := strconv.Atoi(offset); err == nil {
+			filter.Offset = val
+		}
+	}
+
+	forms, err := h.service.ListForms(r.Context(), authCtx.OrganizationID, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forms)
+}
+
+func (h *LeadCaptureHandler) UpdateForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid form ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadCaptureFormUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateForm(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *LeadCaptureHandler) DeleteForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid form ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteForm(r.Context(), authCtx.OrganizationID, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Submit handles POST /api/crm/lead-capture-forms/:id/submit.
+// It responds 201 when a lead was created and 200 when the submission was
+// merged into, or deduplicated against, an existing open lead.
+func (h *LeadCaptureHandler) Submit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid form ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Submit(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Outcome == types.LeadCaptureOutcomeCreated {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
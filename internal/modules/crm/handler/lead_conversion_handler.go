@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ConvertLead handles converting a lead to an opportunity, linking or
+// creating its contact and optionally raising a quotation
+func (h *LeadHandler) ConvertLead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	conversion, err := h.leadService.ConvertLead(r.Context(), orgID, id, req)
+	if err != nil {
+		writeLeadConversionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversion)
+}
+
+func writeLeadConversionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadConversion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrLeadNotConvertible):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
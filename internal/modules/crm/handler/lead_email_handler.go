@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/email/inbound"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxInboundEmailSize bounds a webhook post; providers cap messages with
+// attachments at 20-30 MB
+const maxInboundEmailSize = 30 << 20
+
+type LeadEmailHandler struct {
+	service *service.LeadEmailService
+}
+
+func NewLeadEmailHandler(service *service.LeadEmailService) *LeadEmailHandler {
+	return &LeadEmailHandler{
+		service: service,
+	}
+}
+
+func (h *LeadEmailHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/lead-email-inboxes", h.ListInboxes)
+	router.POST("/api/v1/lead-email-inboxes", h.CreateInbox)
+	router.PUT("/api/v1/lead-email-inboxes/:id", h.UpdateInbox)
+	router.DELETE("/api/v1/lead-email-inboxes/:id", h.DeleteInbox)
+	router.GET("/api/v1/leads/:id/emails", h.ListLeadEmails)
+
+	// Public: email providers post here, authenticated by the inbox token
+	router.POST(service.LeadEmailWebhookPath+":token", h.Receive)
+}
+
+func (h *LeadEmailHandler) ListInboxes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	inboxes, err := h.service.ListInboxes(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeLeadEmailError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inboxes)
+}
+
+func (h *LeadEmailHandler) CreateInbox(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.LeadEmailInboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CreateInbox(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeLeadEmailError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *LeadEmailHandler) UpdateInbox(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid inbox ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadEmailInboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateInbox(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeLeadEmailError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *LeadEmailHandler) DeleteInbox(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid inbox ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteInbox(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeLeadEmailError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLeadEmails handles retrieval of a lead's email threads
+func (h *LeadEmailHandler) ListLeadEmails(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	threads, err := h.service.ListLeadEmails(r.Context(), authCtx.OrganizationID, leadID)
+	if err != nil {
+		writeLeadEmailError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(threads)
+}
+
+// Receive handles an inbound email webhook. Providers retry on any non-2xx
+// response, so only malformed or unauthenticated posts are rejected.
+func (h *LeadEmailHandler) Receive(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboundEmailSize))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := h.service.Receive(r.Context(), ps.ByName("token"), r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeInboundEmailError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeLeadEmailError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadEmailInbox):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeInboundEmailError answers a provider without exposing internal errors
+func writeInboundEmailError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrLeadEmailInboxNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, inbound.ErrSignature):
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+	case errors.Is(err, inbound.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Email could not be processed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+)
+
+// leadUUIDParams maps each UUID query parameter of lead listing and counting
+// to the filter field it sets
+var leadUUIDParams = map[string]func(f *types.LeadFilter) **uuid.UUID{
+	"company_id":     func(f *types.LeadFilter) **uuid.UUID { return &f.CompanyID },
+	"contact_id":     func(f *types.LeadFilter) **uuid.UUID { return &f.ContactID },
+	"user_id":        func(f *types.LeadFilter) **uuid.UUID { return &f.UserID },
+	"team_id":        func(f *types.LeadFilter) **uuid.UUID { return &f.TeamID },
+	"stage_id":       func(f *types.LeadFilter) **uuid.UUID { return &f.StageID },
+	"source_id":      func(f *types.LeadFilter) **uuid.UUID { return &f.SourceID },
+	"medium_id":      func(f *types.LeadFilter) **uuid.UUID { return &f.MediumID },
+	"campaign_id":    func(f *types.LeadFilter) **uuid.UUID { return &f.CampaignID },
+	"lost_reason_id": func(f *types.LeadFilter) **uuid.UUID { return &f.LostReasonID },
+	"country_id":     func(f *types.LeadFilter) **uuid.UUID { return &f.CountryID },
+	"state_id":       func(f *types.LeadFilter) **uuid.UUID { return &f.StateID },
+	"assigned_to":    func(f *types.LeadFilter) **uuid.UUID { return &f.AssignedTo },
+	"created_by":     func(f *types.LeadFilter) **uuid.UUID { return &f.CreatedBy },
+	"updated_by":     func(f *types.LeadFilter) **uuid.UUID { return &f.UpdatedBy },
+}
+
+// leadTextParams maps each text query parameter to the filter field it sets
+var leadTextParams = map[string]func(f *types.LeadFilter) **string{
+	"name":         func(f *types.LeadFilter) **string { return &f.Name },
+	"email":        func(f *types.LeadFilter) **string { return &f.Email },
+	"phone":        func(f *types.LeadFilter) **string { return &f.Phone },
+	"contact_name": func(f *types.LeadFilter) **string { return &f.ContactName },
+	"mobile":       func(f *types.LeadFilter) **string { return &f.Mobile },
+	"city":         func(f *types.LeadFilter) **string { return &f.City },
+	"color":        func(f *types.LeadFilter) **string { return &f.Color },
+}
+
+// parseLeadFilter reads the filters shared by lead listing and counting from
+// query parameters. A malformed value is an error rather than being ignored,
+// which would widen the result to every lead.
+func parseLeadFilter(query url.Values) (types.LeadFilter, error) {
+	filter := types.LeadFilter{}
+
+	matchMode, err := database.ParseMatchMode(query.Get("match_mode"))
+	if err != nil {
+		return filter, err
+	}
+	filter.MatchMode = matchMode
+
+	// Parse string parameters
+	for param, field := range leadTextParams {
+		if value := query.Get(param); value != "" {
+			*field(&filter) = &value
+		}
+	}
+
+	// Parse UUID parameters
+	for param, field := range leadUUIDParams {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsedID, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %q", param, value)
+		}
+		*field(&filter) = &parsedID
+	}
+
+	// Parse tag parameters: tag_ids is comma separated or repeated, tag_id
+	// is its single-valued predecessor
+	for _, param := range []string{"tag_ids", "tag_id"} {
+		for _, value := range query[param] {
+			for _, raw := range strings.Split(value, ",") {
+				if raw = strings.TrimSpace(raw); raw == "" {
+					continue
+				}
+				tagID, err := uuid.Parse(raw)
+				if err != nil {
+					return filter, fmt.Errorf("invalid %s: %q", param, raw)
+				}
+				filter.TagIDs = append(filter.TagIDs, tagID)
+			}
+		}
+	}
+
+	// Parse enum parameters
+	if leadType := query.Get("lead_type"); leadType != "" {
+		typedLeadType := types.LeadType(leadType)
+		filter.LeadType = &typedLeadType
+	}
+	if priority := query.Get("priority"); priority != "" {
+		typedPriority := types.LeadPriority(priority)
+		filter.Priority = &typedPriority
+	}
+	if wonStatus := query.Get("won_status"); wonStatus != "" {
+		typedWonStatus := types.LeadWonStatus(wonStatus)
+		filter.WonStatus = &typedWonStatus
+	}
+	if status := query.Get("status"); status != "" {
+		typedStatus := types.LeadStatus(status)
+		if !typedStatus.IsValid() {
+			return filter, fmt.Errorf("invalid status: %q", status)
+		}
+		filter.Status = &typedStatus
+	}
+
+	// Parse numeric parameters
+	if expectedRevenueMin := query.Get("expected_revenue_min"); expectedRevenueMin != "" {
+		val, err := strconv.ParseFloat(expectedRevenueMin, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid expected_revenue_min: %q", expectedRevenueMin)
+		}
+		filter.ExpectedRevenueMin = &val
+	}
+	if expectedRevenueMax := query.Get("expected_revenue_max"); expectedRevenueMax != "" {
+		val, err := strconv.ParseFloat(expectedRevenueMax, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid expected_revenue_max: %q", expectedRevenueMax)
+		}
+		filter.ExpectedRevenueMax = &val
+	}
+	if probabilityMin := query.Get("probability_min"); probabilityMin != "" {
+		val, err := strconv.Atoi(probabilityMin)
+		if err != nil {
+			return filter, fmt.Errorf("invalid probability_min: %q", probabilityMin)
+		}
+		filter.ProbabilityMin = &val
+	}
+	if probabilityMax := query.Get("probability_max"); probabilityMax != "" {
+		val, err := strconv.Atoi(probabilityMax)
+		if err != nil {
+			return filter, fmt.Errorf("invalid probability_max: %q", probabilityMax)
+		}
+		filter.ProbabilityMax = &val
+	}
+
+	// Parse boolean parameters
+	if active := query.Get("active"); active != "" {
+		val, err := strconv.ParseBool(active)
+		if err != nil {
+			return filter, fmt.Errorf("invalid active: %q", active)
+		}
+		filter.Active = &val
+	}
+
+	if err := parseComputedBounds(query, &filter); err != nil {
+		return filter, err
+	}
+	parseCustomFieldFilters(query, &filter)
+	return filter, nil
+}
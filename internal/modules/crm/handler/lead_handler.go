@@ -2,12 +2,22 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/query"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 )
@@ -15,89 +25,150 @@ import (
 // LeadHandler handles HTTP requests for leads
 type LeadHandler struct {
 	leadService *service.LeadService
+	// legacyFilterRoutes keeps the deprecated by-<filter> routes, which
+	// GET /api/v1/leads query parameters replace
+	legacyFilterRoutes bool
 }
 
-// NewLeadHandler creates a new LeadHandler
+// NewLeadHandler creates a new LeadHandler, serving the deprecated
+// by-<filter> routes until SetLegacyFilterRoutes turns them off
 func NewLeadHandler(leadService *service.LeadService) *LeadHandler {
 	return &LeadHandler{
-		leadService: leadService,
+		leadService:        leadService,
+		legacyFilterRoutes: true,
 	}
 }
 
-// RegisterRoutes registers lead routes
+// SetLegacyFilterRoutes sets whether the deprecated by-<filter> routes are
+// served. It must be called before RegisterRoutes.
+func (h *LeadHandler) SetLegacyFilterRoutes(enabled bool) {
+	h.legacyFilterRoutes = enabled
+}
+
+// RegisterRoutes registers lead routes. Leads are filtered with query
+// parameters on GET /api/v1/leads; the views named below sit beside
+// /api/v1/leads/:id and are told apart from lead IDs by routeBySegment.
 func (h *LeadHandler) RegisterRoutes(router *httprouter.Router) {
+	views := map[string]httprouter.Handle{
+		"count":  h.CountLeads,
+		"search": h.SearchLeads,
+		"board":  h.GetLeadBoard,
+
+		// Analytics endpoints
+		"pipeline-value":            h.GetPipelineValue,
+		"pipeline-value-by-stage":   h.GetPipelineValueByStage,
+		"conversion-rate":           h.GetConversionRate,
+		"win-rate":                  h.GetWinRate,
+		"loss-rate":                 h.GetLossRate,
+		"average-conversion-time":   h.GetAverageConversionTime,
+		"average-win-time":          h.GetAverageWinTime,
+		"average-loss-time":         h.GetAverageLossTime,
+		"average-expected-revenue":  h.GetAverageExpectedRevenue,
+		"average-probability":       h.GetAverageProbability,
+		"average-recurring-revenue": h.GetAverageRecurringRevenue,
+		"total-expected-revenue":    h.GetTotalExpectedRevenue,
+		"total-recurring-revenue":   h.GetTotalRecurringRevenue,
+		"recurring-revenue-report":  h.GetRecurringRevenueReport,
+		"stage-velocity":            h.GetStageVelocity,
+		"probability-accuracy":      h.GetProbabilityAccuracy,
+		"sla-compliance":            h.GetSLACompliance,
+		"sla-breaches":              h.GetSLABreaches,
+		"rotation-stats":            h.GetRotationStats,
+		"pipeline-comparison":       h.ComparePipeline,
+
+		// Saved views
+		"overdue":    h.GetOverdueLeads,
+		"high-value": h.GetHighValueLeads,
+		"recent":     h.GetRecentLeads,
+		"trash":      h.ListDeletedLeads,
+
+		// Count endpoints
+		"count-by-stage":       h.CountLeadsByStage,
+		"count-by-priority":    h.CountLeadsByPriority,
+		"count-by-type":        h.CountLeadsByType,
+		"count-by-source":      h.CountLeadsBySource,
+		"count-by-medium":      h.CountLeadsByMedium,
+		"count-by-campaign":    h.CountLeadsByCampaign,
+		"count-by-team":        h.CountLeadsByTeam,
+		"count-by-user":        h.CountLeadsByUser,
+		"count-by-lost-reason": h.CountLeadsByLostReason,
+		"count-by-won-status":  h.CountLeadsByWonStatus,
+		"count-by-country":     h.CountLeadsByCountry,
+		"count-by-state":       h.CountLeadsByState,
+		"count-by-city":        h.CountLeadsByCity,
+	}
+
+	// Bulk endpoints, ownership transfer, and searching with a query filter tree
+	actions := map[string]httprouter.Handle{
+		"bulk-update": h.BulkUpdateLeads,
+		"bulk-delete": h.BulkDeleteLeads,
+		"reassign":    h.ReassignLeads,
+		"search":      h.QueryLeads,
+	}
+
 	router.POST("/api/v1/leads", h.CreateLead)
-	router.GET("/api/v1/leads/:id", h.GetLead)
+	router.GET("/api/v1/leads", h.ListLeads)
+	router.GET("/api/v1/leads/:id", routeBySegment("id", views, h.GetLead))
+	router.POST("/api/v1/leads/:id", routeBySegment("id", actions, nil))
 	router.PUT("/api/v1/leads/:id", h.UpdateLead)
 	router.DELETE("/api/v1/leads/:id", h.DeleteLead)
-	router.GET("/api/v1/leads", h.ListLeads)
-	router.GET("/api/v1/leads/count", h.CountLeads)
-
-	// Analytics endpoints
-	router.GET("/api/v1/leads/pipeline-value", h.GetPipelineValue)
-	router.GET("/api/v1/leads/pipeline-value-by-stage", h.GetPipelineValueByStage)
-	router.GET("/api/v1/leads/conversion-rate", h.GetConversionRate)
-	router.GET("/api/v1/leads/win-rate", h.GetWinRate)
-	router.GET("/api/v1/leads/loss-rate", h.GetLossRate)
-	router.GET("/api/v1/leads/average-conversion-time", h.GetAverageConversionTime)
-	router.GET("/api/v1/leads/average-win-time", h.GetAverageWinTime)
-	router.GET("/api/v1/leads/average-loss-time", h.GetAverageLossTime)
-	router.GET("/api/v1/leads/average-expected-revenue", h.GetAverageExpectedRevenue)
-	router.GET("/api/v1/leads/average-probability", h.GetAverageProbability)
-	router.GET("/api/v1/leads/average-recurring-revenue", h.GetAverageRecurringRevenue)
-	router.GET("/api/v1/leads/total-expected-revenue", h.GetTotalExpectedRevenue)
-	router.GET("/api/v1/leads/total-recurring-revenue", h.GetTotalRecurringRevenue)
-
-	// Filter endpoints
-	router.GET("/api/v1/leads/by-contact/:contactID", h.GetLeadsByContact)
-	router.GET("/api/v1/leads/by-user/:userID", h.GetLeadsByUser)
-	router.GET("/api/v1/leads/by-team/:teamID", h.GetLeadsByTeam)
-	router.GET("/api/v1/leads/by-stage/:stageID", h.GetLeadsByStage)
-	router.GET("/api/v1/leads/by-source/:sourceID", h.GetLeadsBySource)
-	router.GET("/api/v1/leads/by-campaign/:campaignID", h.GetLeadsByCampaign)
-	router.GET("/api/v1/leads/by-medium/:mediumID", h.GetLeadsByMedium)
-	router.GET("/api/v1/leads/by-tag/:tagID", h.GetLeadsByTag)
-	router.GET("/api/v1/leads/by-company/:companyID", h.GetLeadsByCompany)
-	router.GET("/api/v1/leads/by-country/:countryID", h.GetLeadsByCountry)
-	router.GET("/api/v1/leads/by-state/:stateID", h.GetLeadsByState)
-	router.GET("/api/v1/leads/by-city/:city", h.GetLeadsByCity)
-	router.GET("/api/v1/leads/by-lost-reason/:lostReasonID", h.GetLeadsByLostReason)
-	router.GET("/api/v1/leads/by-created-by/:createdBy", h.GetLeadsByCreatedBy)
-	router.GET("/api/v1/leads/by-updated-by/:updatedBy", h.GetLeadsByUpdatedBy)
-	router.GET("/api/v1/leads/by-color/:color", h.GetLeadsByColor)
-	router.GET("/api/v1/leads/overdue", h.GetOverdueLeads)
-	router.GET("/api/v1/leads/high-value", h.GetHighValueLeads)
-	router.GET("/api/v1/leads/recent", h.GetRecentLeads)
-	router.GET("/api/v1/leads/by-status/:status", h.GetLeadsByStatus)
-	router.GET("/api/v1/leads/by-priority/:priority", h.GetLeadsByPriority)
-	router.GET("/api/v1/leads/by-type/:leadType", h.GetLeadsByType)
-	router.GET("/api/v1/leads/by-won-status/:wonStatus", h.GetLeadsByWonStatus)
-	router.GET("/api/v1/leads/by-active-status/:active", h.GetLeadsByActiveStatus)
-
-	// Count endpoints
-	router.GET("/api/v1/leads/count-by-stage", h.CountLeadsByStage)
-	router.GET("/api/v1/leads/count-by-priority", h.CountLeadsByPriority)
-	router.GET("/api/v1/leads/count-by-type", h.CountLeadsByType)
-	router.GET("/api/v1/leads/count-by-source", h.CountLeadsBySource)
-	router.GET("/api/v1/leads/count-by-medium", h.CountLeadsByMedium)
-	router.GET("/api/v1/leads/count-by-campaign", h.CountLeadsByCampaign)
-	router.GET("/api/v1/leads/count-by-team", h.CountLeadsByTeam)
-	router.GET("/api/v1/leads/count-by-user", h.CountLeadsByUser)
-	router.GET("/api/v1/leads/count-by-lost-reason", h.CountLeadsByLostReason)
-	router.GET("/api/v1/leads/count-by-won-status", h.CountLeadsByWonStatus)
-	router.GET("/api/v1/leads/count-by-country", h.CountLeadsByCountry)
-	router.GET("/api/v1/leads/count-by-state", h.CountLeadsByState)
-	router.GET("/api/v1/leads/count-by-city", h.CountLeadsByCity)
+
+	// SLA endpoints
+	router.GET("/api/v1/lead-sla-policies", h.ListSLAPolicies)
+	router.POST("/api/v1/lead-sla-policies", h.CreateSLAPolicy)
+	router.PUT("/api/v1/lead-sla-policies/:id", h.UpdateSLAPolicy)
+	router.DELETE("/api/v1/lead-sla-policies/:id", h.DeleteSLAPolicy)
+
+	// Cadence endpoints
+	router.GET("/api/v1/lead-cadences", h.ListCadences)
+	router.POST("/api/v1/lead-cadences", h.CreateCadence)
+	router.GET("/api/v1/lead-cadences/:id", h.GetCadence)
+	router.PUT("/api/v1/lead-cadences/:id", h.UpdateCadence)
+	router.DELETE("/api/v1/lead-cadences/:id", h.DeleteCadence)
+	router.GET("/api/v1/lead-cadence-performance", h.GetCadencePerformance)
+	router.GET("/api/v1/leads/:id/cadences", h.GetLeadCadences)
+	router.POST("/api/v1/lead-cadence-tasks/:id/skip", h.SkipCadenceTask)
+	router.POST("/api/v1/lead-cadence-enrollments/:id/stop", h.StopCadenceEnrollment)
+
+	// Stage transition endpoints
+	router.POST("/api/v1/leads/:id/move-stage", h.MoveStage)
+	router.GET("/api/v1/leads/:id/stage-history", h.GetStageHistory)
+	router.GET("/api/v1/lead-stage-transitions", h.GetStageTransitions)
+	router.PUT("/api/v1/lead-stage-transitions", h.SetStageTransitions)
+
+	// Trash endpoints
+	router.POST("/api/v1/leads/:id/restore", h.RestoreLead)
+
+	// Conversion endpoints
+	router.POST("/api/v1/leads/:id/convert", h.ConvertLead)
+
+	// Outcome endpoints
+	router.POST("/api/v1/leads/:id/mark-won", h.MarkWon)
+	router.POST("/api/v1/leads/:id/mark-lost", h.MarkLost)
+
+	// Change history endpoints
+	router.GET("/api/v1/leads/:id/history", h.GetLeadHistory)
+	router.GET("/api/v1/lead-audit-log", h.ExportLeadAudit)
+
+	// Attachment endpoints
+	router.POST("/api/v1/leads/:id/attachments", h.UploadLeadAttachment)
+	router.GET("/api/v1/leads/:id/attachments", h.ListLeadAttachments)
+	router.GET("/api/v1/leads/:id/attachments/:attachment_id", h.GetLeadAttachment)
+	router.DELETE("/api/v1/leads/:id/attachments/:attachment_id", h.DeleteLeadAttachment)
+
+	// Deprecated filter endpoints, replaced by GET /api/v1/leads query parameters
+	if h.legacyFilterRoutes {
+		router.NotFound = legacyLeadFilterRoutes(h.ListLeads, router.NotFound)
+	}
 }
 
 // CreateLead handles lead creation
 func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	var req types.LeadCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -106,6 +177,13 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request, _ httpr
 	}
 
 	lead, err := h.leadService.CreateLead(r.Context(), orgID, req)
+	if errors.Is(err, types.ErrLostReasonRequired) || errors.Is(err, customfields.ErrInvalidValue) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if entitlements.WriteError(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -117,12 +195,11 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request, _ httpr
 
 // GetLead handles lead retrieval
 func (h *LeadHandler) GetLead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	id, err := uuid.Parse(ps.ByName("id"))
 	if err != nil {
@@ -137,17 +214,17 @@ func (h *LeadHandler) GetLead(w http.ResponseWriter, r *http.Request, ps httprou
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", leadETag(lead.Version))
 	json.NewEncoder(w).Encode(lead)
 }
 
 // UpdateLead handles lead updates
 func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	id, err := uuid.Parse(ps.ByName("id"))
 	if err != nil {
@@ -161,24 +238,65 @@ func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request, ps http
 		return
 	}
 
+	// If-Match carries the version like the body's version field does
+	if value := r.Header.Get("If-Match"); value != "" && value != "*" {
+		version, err := parseLeadETag(value)
+		if err != nil {
+			http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+		if req.Version != nil && *req.Version != version {
+			http.Error(w, "If-Match and version disagree", http.StatusBadRequest)
+			return
+		}
+		req.Version = &version
+	}
+
 	lead, err := h.leadService.UpdateLead(r.Context(), orgID, id, req)
+	var conflict *types.LeadVersionConflictError
+	if errors.As(err, &conflict) {
+		// Answer the stored lead so the client can merge into it and retry
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", leadETag(conflict.Current.Version))
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": conflict.Error(),
+			"lead":  conflict.Current,
+		})
+		return
+	}
+	if errors.Is(err, types.ErrLostReasonRequired) || errors.Is(err, customfields.ErrInvalidValue) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", leadETag(lead.Version))
 	json.NewEncoder(w).Encode(lead)
 }
 
+// leadETag is the entity tag of a lead version
+func leadETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseLeadETag reads the lead version of an entity tag, weak or strong
+func parseLeadETag(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	return strconv.Atoi(strings.Trim(value, `"`))
+}
+
 // DeleteLead handles lead deletion
 func (h *LeadHandler) DeleteLead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	id, err := uuid.Parse(ps.ByName("id"))
 	if err != nil {
@@ -194,144 +312,24 @@ func (h *LeadHandler) DeleteLead(w http.ResponseWriter, r *http.Request, ps http
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ListLeads handles lead listing
+// ListLeads handles lead listing, answering a page of leads as
+// {data, total, limit, offset} so clients need not count separately
 func (h *LeadHandler) ListLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
-	// Parse query parameters
-	filter := types.LeadFilter{}
-
-	if name := r.URL.Query().Get("name"); name != "" {
-		filter.Name = &name
-	}
-	if email := r.URL.Query().Get("email"); email != "" {
-		filter.Email = &email
-	}
-	if phone := r.URL.Query().Get("phone"); phone != "" {
-		filter.Phone = &phone
-	}
-	if contactName := r.URL.Query().Get("contact_name"); contactName != "" {
-		filter.ContactName = &contactName
-	}
-	if mobile := r.URL.Query().Get("mobile"); mobile != "" {
-		filter.Mobile = &mobile
-	}
-
-	// Parse UUID parameters
-	if companyID := r.URL.Query().Get("company_id"); companyID != "" {
-		parsedID, err := uuid.Parse(companyID)
-		if err == nil {
-			filter.CompanyID = &parsedID
-		}
-	}
-	if contactID := r.URL.Query().Get("contact_id"); contactID != "" {
-		parsedID, err := uuid.Parse(contactID)
-		if err == nil {
-			filter.ContactID = &parsedID
-		}
-	}
-	if userID := r.URL.Query().Get("user_id"); userID != "" {
-		parsedID, err := uuid.Parse(userID)
-		if err == nil {
-			filter.UserID = &parsedID
-		}
-	}
-	if teamID := r.URL.Query().Get("team_id"); teamID != "" {
-		parsedID, err := uuid.Parse(teamID)
-		if err == nil {
-			filter.TeamID = &parsedID
-		}
-	}
-	if stageID := r.URL.Query().Get("stage_id"); stageID != "" {
-		parsedID, err := uuid.Parse(stageID)
-		if err == nil {
-			filter.StageID = &parsedID
-		}
-	}
-	if sourceID := r.URL.Query().Get("source_id"); sourceID != "" {
-		parsedID, err := uuid.Parse(sourceID)
-		if err == nil {
-			filter.SourceID = &parsedID
-		}
-	}
-	if mediumID := r.URL.Query().Get("medium_id"); mediumID != "" {
-		parsedID, err := uuid.Parse(mediumID)
-		if err == nil {
-			filter.MediumID = &parsedID
-		}
-	}
-	if campaignID := r.URL.Query().Get("campaign_id"); campaignID != "" {
-		parsedID, err := uuid.Parse(campaignID)
-		if err == nil {
-			filter.CampaignID = &parsedID
-		}
-	}
-	if lostReasonID := r.URL.Query().Get("lost_reason_id"); lostReasonID != "" {
-		parsedID, err := uuid.Parse(lostReasonID)
-		if err == nil {
-			filter.LostReasonID = &parsedID
-		}
-	}
-	if countryID := r.URL.Query().Get("country_id"); countryID != "" {
-		parsedID, err := uuid.Parse(countryID)
-		if err == nil {
-			filter.CountryID = &parsedID
-		}
-	}
-	if stateID := r.URL.Query().Get("state_id"); stateID != "" {
-		parsedID, err := uuid.Parse(stateID)
-		if err == nil {
-			filter.StateID = &parsedID
-		}
-	}
-
-	// Parse enum parameters
-	if leadType := r.URL.Query().Get("lead_type"); leadType != "" {
-		typedLeadType := types.LeadType(leadType)
-		filter.LeadType = &typedLeadType
-	}
-	if priority := r.URL.Query().Get("priority"); priority != "" {
-		typedPriority := types.LeadPriority(priority)
-		filter.Priority = &typedPriority
-	}
-	if wonStatus := r.URL.Query().Get("won_status"); wonStatus != "" {
-		typedWonStatus := types.LeadWonStatus(wonStatus)
-		filter.WonStatus = &typedWonStatus
-	}
-
-	// Parse numeric parameters
-	if expectedRevenueMin := r.URL.Query().Get("expected_revenue_min"); expectedRevenueMin != "" {
-		if val, err := strconv.ParseFloat(expectedRevenueMin, 64); err == nil {
-			filter.ExpectedRevenueMin = &val
-		}
-	}
-	if expectedRevenueMax := r.URL.Query().Get("expected_revenue_max"); expectedRevenueMax != "" {
-		if val, err := strconv.ParseFloat(expectedRevenueMax, 64); err == nil {
-			filter.ExpectedRevenueMax = &val
-		}
-	}
-	if probabilityMin := r.URL.Query().Get("probability_min"); probabilityMin != "" {
-		if val, err := strconv.Atoi(probabilityMin); err == nil {
-			filter.ProbabilityMin = &val
-		}
-	}
-	if probabilityMax := r.URL.Query().Get("probability_max"); probabilityMax != "" {
-		if val, err := strconv.Atoi(probabilityMax); err == nil {
-			filter.ProbabilityMax = &val
-		}
+	filter, err := parseLeadFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Parse boolean parameters
-	if active := r.URL.Query().Get("active"); active != "" {
-		if val, err := strconv.ParseBool(active); err == nil {
-			filter.Active = &val
-		}
-	}
+	// Parse sort parameters; the service rejects unknown fields and directions
+	filter.SortBy = types.LeadSortField(strings.ToLower(r.URL.Query().Get("sort_by")))
+	filter.SortDir = types.SortDirection(strings.ToLower(r.URL.Query().Get("sort_dir")))
 
 	// Parse pagination parameters
 	if limit := r.URL.Query().Get("limit"); limit != "" {
@@ -345,158 +343,92 @@ func (h *LeadHandler) ListLeads(w http.ResponseWriter, r *http.Request, _ httpro
 		}
 	}
 
-	leads, err := h.leadService.ListLeads(r.Context(), orgID, filter)
+	page, err := h.leadService.ListLeadsPage(r.Context(), orgID, filter)
 	if err != nil {
+		if errors.Is(err, computed.ErrUnknownField) || errors.Is(err, types.ErrInvalidLeadSort) ||
+			errors.Is(err, customfields.ErrUnknownField) || errors.Is(err, customfields.ErrInvalidValue) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
+	json.NewEncoder(w).Encode(page)
 }
 
-// CountLeads handles lead counting
-func (h *LeadHandler) CountLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+// leadQueryRequest is the body of a lead search with a query filter tree
+type leadQueryRequest struct {
+	Query   *query.Node `json:"query"`
+	SortBy  string      `json:"sort_by"`
+	SortDir string      `json:"sort_dir"`
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+}
+
+// QueryLeads handles searching leads with a boolean filter tree over their
+// fields, custom fields and tags, answering a page of leads and the total
+func (h *LeadHandler) QueryLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
-	// Parse query parameters (similar to ListLeads)
-	filter := types.LeadFilter{}
-
-	// Parse string parameters
-	if name := r.URL.Query().Get("name"); name != "" {
-		filter.Name = &name
-	}
-	if email := r.URL.Query().Get("email"); email != "" {
-		filter.Email = &email
-	}
-	if phone := r.URL.Query().Get("phone"); phone != "" {
-		filter.Phone = &phone
-	}
-	if contactName := r.URL.Query().Get("contact_name"); contactName != "" {
-		filter.ContactName = &contactName
-	}
-	if mobile := r.URL.Query().Get("mobile"); mobile != "" {
-		filter.Mobile = &mobile
+	var req leadQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Parse UUID parameters
-	if companyID := r.URL.Query().Get("company_id"); companyID != "" {
-		parsedID, err := uuid.Parse(companyID)
-		if err == nil {
-			filter.CompanyID = &parsedID
-		}
-	}
-	if contactID := r.URL.Query().Get("contact_id"); contactID != "" {
-		parsedID, err := uuid.Parse(contactID)
-		if err == nil {
-			filter.ContactID = &parsedID
-		}
-	}
-	if userID := r.URL.Query().Get("user_id"); userID != "" {
-		parsedID, err := uuid.Parse(userID)
-		if err == nil {
-			filter.UserID = &parsedID
-		}
-	}
-	if teamID := r.URL.Query().Get("team_id"); teamID != "" {
-		parsedID, err := uuid.Parse(teamID)
-		if err == nil {
-			filter.TeamID = &parsedID
-		}
-	}
-	if stageID := r.URL.Query().Get("stage_id"); stageID != "" {
-		parsedID, err := uuid.Parse(stageID)
-		if err == nil {
-			filter.StageID = &parsedID
-		}
-	}
-	if sourceID := r.URL.Query().Get("source_id"); sourceID != "" {
-		parsedID, err := uuid.Parse(sourceID)
-		if err == nil {
-			filter.SourceID = &parsedID
-		}
-	}
-	if mediumID := r.URL.Query().Get("medium_id"); mediumID != "" {
-		parsedID, err := uuid.Parse(mediumID)
-		if err == nil {
-			filter.MediumID = &parsedID
-		}
-	}
-	if campaignID := r.URL.Query().Get("campaign_id"); campaignID != "" {
-		parsedID, err := uuid.Parse(campaignID)
-		if err == nil {
-			filter.CampaignID = &parsedID
-		}
-	}
-	if lostReasonID := r.URL.Query().Get("lost_reason_id"); lostReasonID != "" {
-		parsedID, err := uuid.Parse(lostReasonID)
-		if err == nil {
-			filter.LostReasonID = &parsedID
-		}
-	}
-	if countryID := r.URL.Query().Get("country_id"); countryID != "" {
-		parsedID, err := uuid.Parse(countryID)
-		if err == nil {
-			filter.CountryID = &parsedID
-		}
-	}
-	if stateID := r.URL.Query().Get("state_id"); stateID != "" {
-		parsedID, err := uuid.Parse(stateID)
-		if err == nil {
-			filter.StateID = &parsedID
-		}
+	filter := types.LeadFilter{
+		Query:   req.Query,
+		SortBy:  types.LeadSortField(strings.ToLower(req.SortBy)),
+		SortDir: types.SortDirection(strings.ToLower(req.SortDir)),
+		Limit:   req.Limit,
+		Offset:  req.Offset,
 	}
 
-	// Parse enum parameters
-	if leadType := r.URL.Query().Get("lead_type"); leadType != "" {
-		typedLeadType := types.LeadType(leadType)
-		filter.LeadType = &typedLeadType
-	}
-	if priority := r.URL.Query().Get("priority"); priority != "" {
-		typedPriority := types.LeadPriority(priority)
-		filter.Priority = &typedPriority
-	}
-	if wonStatus := r.URL.Query().Get("won_status"); wonStatus != "" {
-		typedWonStatus := types.LeadWonStatus(wonStatus)
-		filter.WonStatus = &typedWonStatus
+	page, err := h.leadService.ListLeadsPage(r.Context(), orgID, filter)
+	if err != nil {
+		writeLeadQueryError(w, err)
+		return
 	}
 
-	// Parse numeric parameters
-	if expectedRevenueMin := r.URL.Query().Get("expected_revenue_min"); expectedRevenueMin != "" {
-		if val, err := strconv.ParseFloat(expectedRevenueMin, 64); err == nil {
-			filter.ExpectedRevenueMin = &val
-		}
-	}
-	if expectedRevenueMax := r.URL.Query().Get("expected_revenue_max"); expectedRevenueMax != "" {
-		if val, err := strconv.ParseFloat(expectedRevenueMax, 64); err == nil {
-			filter.ExpectedRevenueMax = &val
-		}
-	}
-	if probabilityMin := r.URL.Query().Get("probability_min"); probabilityMin != "" {
-		if val, err := strconv.Atoi(probabilityMin); err == nil {
-			filter.ProbabilityMin = &val
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func writeLeadQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, query.ErrInvalidQuery) || errors.Is(err, types.ErrInvalidLeadSort) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if probabilityMax := r.URL.Query().Get("probability_max"); probabilityMax != "" {
-		if val, err := strconv.Atoi(probabilityMax); err == nil {
-			filter.ProbabilityMax = &val
-		}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// CountLeads handles lead counting
+func (h *LeadHandler) CountLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
 	}
+	orgID := authCtx.OrganizationID
 
-	// Parse boolean parameters
-	if active := r.URL.Query().Get("active"); active != "" {
-		if val, err := strconv.ParseBool(active); err == nil {
-			filter.Active = &val
-		}
+	filter, err := parseLeadFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	count, err := h.leadService.CountLeads(r.Context(), orgID, filter)
 	if err != nil {
+		if errors.Is(err, computed.ErrUnknownField) ||
+			errors.Is(err, customfields.ErrUnknownField) || errors.Is(err, customfields.ErrInvalidValue) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -505,14 +437,59 @@ func (h *LeadHandler) CountLeads(w http.ResponseWriter, r *http.Request, _ httpr
 	json.NewEncoder(w).Encode(map[string]int{"count": count})
 }
 
+// parseComputedBounds reads computed.<name>_min and computed.<name>_max
+// query parameters into the filter's computed field bounds
+func parseComputedBounds(query url.Values, filter *types.LeadFilter) error {
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "computed.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		var bounds *map[string]float64
+		switch {
+		case strings.HasSuffix(name, "_min"):
+			name, bounds = strings.TrimSuffix(name, "_min"), &filter.ComputedMin
+		case strings.HasSuffix(name, "_max"):
+			name, bounds = strings.TrimSuffix(name, "_max"), &filter.ComputedMax
+		default:
+			return fmt.Errorf("invalid computed filter %q: use computed.<name>_min or computed.<name>_max", key)
+		}
+
+		val, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		if *bounds == nil {
+			*bounds = make(map[string]float64)
+		}
+		(*bounds)[name] = val
+	}
+	return nil
+}
+
+// parseCustomFieldFilters reads custom.<name> query parameters into the
+// filter's custom field equality filters
+func parseCustomFieldFilters(query url.Values, filter *types.LeadFilter) {
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "custom.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if filter.CustomFieldFilters == nil {
+			filter.CustomFieldFilters = make(map[string]string)
+		}
+		filter.CustomFieldFilters[name] = values[0]
+	}
+}
+
 // GetPipelineValue handles pipeline value retrieval
 func (h *LeadHandler) GetPipelineValue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	pipelineValue, err := h.leadService.GetLeadPipelineValue(r.Context(), orgID)
 	if err != nil {
@@ -526,12 +503,11 @@ func (h *LeadHandler) GetPipelineValue(w http.ResponseWriter, r *http.Request, _
 
 // GetPipelineValueByStage handles pipeline value by stage retrieval
 func (h *LeadHandler) GetPipelineValueByStage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	pipelineValueByStage, err := h.leadService.GetLeadPipelineValueByStage(r.Context(), orgID)
 	if err != nil {
@@ -545,12 +521,11 @@ func (h *LeadHandler) GetPipelineValueByStage(w http.ResponseWriter, r *http.Req
 
 // GetConversionRate handles conversion rate retrieval
 func (h *LeadHandler) GetConversionRate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	conversionRate, err := h.leadService.GetLeadConversionRate(r.Context(), orgID)
 	if err != nil {
@@ -564,12 +539,11 @@ func (h *LeadHandler) GetConversionRate(w http.ResponseWriter, r *http.Request,
 
 // GetWinRate handles win rate retrieval
 func (h *LeadHandler) GetWinRate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	winRate, err := h.leadService.GetLeadWinRate(r.Context(), orgID)
 	if err != nil {
@@ -583,12 +557,11 @@ func (h *LeadHandler) GetWinRate(w http.ResponseWriter, r *http.Request, _ httpr
 
 // GetLossRate handles loss rate retrieval
 func (h *LeadHandler) GetLossRate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	lossRate, err := h.leadService.GetLeadLossRate(r.Context(), orgID)
 	if err != nil {
@@ -602,12 +575,11 @@ func (h *LeadHandler) GetLossRate(w http.ResponseWriter, r *http.Request, _ http
 
 // GetAverageConversionTime handles average conversion time retrieval
 func (h *LeadHandler) GetAverageConversionTime(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgConversionTime, err := h.leadService.GetLeadAverageConversionTime(r.Context(), orgID)
 	if err != nil {
@@ -621,12 +593,11 @@ func (h *LeadHandler) GetAverageConversionTime(w http.ResponseWriter, r *http.Re
 
 // GetAverageWinTime handles average win time retrieval
 func (h *LeadHandler) GetAverageWinTime(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgWinTime, err := h.leadService.GetLeadAverageWinTime(r.Context(), orgID)
 	if err != nil {
@@ -640,12 +611,11 @@ func (h *LeadHandler) GetAverageWinTime(w http.ResponseWriter, r *http.Request,
 
 // GetAverageLossTime handles average loss time retrieval
 func (h *LeadHandler) GetAverageLossTime(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgLossTime, err := h.leadService.GetLeadAverageLossTime(r.Context(), orgID)
 	if err != nil {
@@ -659,12 +629,11 @@ func (h *LeadHandler) GetAverageLossTime(w http.ResponseWriter, r *http.Request,
 
 // GetAverageExpectedRevenue handles average expected revenue retrieval
 func (h *LeadHandler) GetAverageExpectedRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgExpectedRevenue, err := h.leadService.GetLeadAverageExpectedRevenue(r.Context(), orgID)
 	if err != nil {
@@ -678,12 +647,11 @@ func (h *LeadHandler) GetAverageExpectedRevenue(w http.ResponseWriter, r *http.R
 
 // GetAverageProbability handles average probability retrieval
 func (h *LeadHandler) GetAverageProbability(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgProbability, err := h.leadService.GetLeadAverageProbability(r.Context(), orgID)
 	if err != nil {
@@ -697,12 +665,11 @@ func (h *LeadHandler) GetAverageProbability(w http.ResponseWriter, r *http.Reque
 
 // GetAverageRecurringRevenue handles average recurring revenue retrieval
 func (h *LeadHandler) GetAverageRecurringRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	avgRecurringRevenue, err := h.leadService.GetLeadAverageRecurringRevenue(r.Context(), orgID)
 	if err != nil {
@@ -714,452 +681,82 @@ func (h *LeadHandler) GetAverageRecurringRevenue(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(map[string]float64{"average_recurring_revenue": avgRecurringRevenue})
 }
 
-// GetTotalExpectedRevenue handles total expected revenue retrieval
-func (h *LeadHandler) GetTotalExpectedRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	totalExpectedRevenue, err := h.leadService.GetLeadTotalExpectedRevenue(r.Context(), orgID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]float64{"total_expected_revenue": totalExpectedRevenue})
-}
-
-// GetTotalRecurringRevenue handles total recurring revenue retrieval
-func (h *LeadHandler) GetTotalRecurringRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	totalRecurringRevenue, err := h.leadService.GetLeadTotalRecurringRevenue(r.Context(), orgID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]float64{"total_recurring_revenue": totalRecurringRevenue})
-}
-
-// GetLeadsByContact handles leads by contact retrieval
-func (h *LeadHandler) GetLeadsByContact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	contactID, err := uuid.Parse(ps.ByName("contactID"))
-	if err != nil {
-		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByContact(r.Context(), orgID, contactID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByUser handles leads by user retrieval
-func (h *LeadHandler) GetLeadsByUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	userID, err := uuid.Parse(ps.ByName("userID"))
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByUser(r.Context(), orgID, userID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByTeam handles leads by team retrieval
-func (h *LeadHandler) GetLeadsByTeam(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	teamID, err := uuid.Parse(ps.ByName("teamID"))
-	if err != nil {
-		http.Error(w, "Invalid team ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByTeam(r.Context(), orgID, teamID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByStage handles leads by stage retrieval
-func (h *LeadHandler) GetLeadsByStage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	stageID, err := uuid.Parse(ps.ByName("stageID"))
-	if err != nil {
-		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByStage(r.Context(), orgID, stageID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsBySource handles leads by source retrieval
-func (h *LeadHandler) GetLeadsBySource(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	sourceID, err := uuid.Parse(ps.ByName("sourceID"))
-	if err != nil {
-		http.Error(w, "Invalid source ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsBySource(r.Context(), orgID, sourceID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByCampaign handles leads by campaign retrieval
-func (h *LeadHandler) GetLeadsByCampaign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	campaignID, err := uuid.Parse(ps.ByName("campaignID"))
-	if err != nil {
-		http.Error(w, "Invalid campaign ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByCampaign(r.Context(), orgID, campaignID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByMedium handles leads by medium retrieval
-func (h *LeadHandler) GetLeadsByMedium(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	mediumID, err := uuid.Parse(ps.ByName("mediumID"))
-	if err != nil {
-		http.Error(w, "Invalid medium ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByMedium(r.Context(), orgID, mediumID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByTag handles leads by tag retrieval
-func (h *LeadHandler) GetLeadsByTag(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	tagID, err := uuid.Parse(ps.ByName("tagID"))
-	if err != nil {
-		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByTag(r.Context(), orgID, tagID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByCompany handles leads by company retrieval
-func (h *LeadHandler) GetLeadsByCompany(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+// GetRecurringRevenueReport handles the MRR and ARR report, optionally for
+// the pipeline of one team_id and the leads closing in one fiscal period
+func (h *LeadHandler) GetRecurringRevenueReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
-	companyID, err := uuid.Parse(ps.ByName("companyID"))
-	if err != nil {
-		http.Error(w, "Invalid company ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByCompany(r.Context(), orgID, companyID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByCountry handles leads by country retrieval
-func (h *LeadHandler) GetLeadsByCountry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	countryID, err := uuid.Parse(ps.ByName("countryID"))
-	if err != nil {
-		http.Error(w, "Invalid country ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByCountry(r.Context(), orgID, countryID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByState handles leads by state retrieval
-func (h *LeadHandler) GetLeadsByState(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	stateID, err := uuid.Parse(ps.ByName("stateID"))
-	if err != nil {
-		http.Error(w, "Invalid state ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByState(r.Context(), orgID, stateID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByCity handles leads by city retrieval
-func (h *LeadHandler) GetLeadsByCity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	city := ps.ByName("city")
-	if city == "" {
-		http.Error(w, "Invalid city", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByCity(r.Context(), orgID, city)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByLostReason handles leads by lost reason retrieval
-func (h *LeadHandler) GetLeadsByLostReason(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	lostReasonID, err := uuid.Parse(ps.ByName("lostReasonID"))
-	if err != nil {
-		http.Error(w, "Invalid lost reason ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByLostReason(r.Context(), orgID, lostReasonID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByCreatedBy handles leads by created by retrieval
-func (h *LeadHandler) GetLeadsByCreatedBy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
+	var teamID *uuid.UUID
+	if value := r.URL.Query().Get("team_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamID = &id
 	}
 
-	createdBy, err := uuid.Parse(ps.ByName("createdBy"))
-	if err != nil {
-		http.Error(w, "Invalid created by ID", http.StatusBadRequest)
+	report, err := h.leadService.GetRecurringRevenueReport(r.Context(), orgID, teamID, r.URL.Query().Get("period"))
+	if errors.Is(err, fiscal.ErrInvalidPeriod) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	leads, err := h.leadService.GetLeadsByCreatedBy(r.Context(), orgID, createdBy)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
+	json.NewEncoder(w).Encode(report)
 }
 
-// GetLeadsByUpdatedBy handles leads by updated by retrieval
-func (h *LeadHandler) GetLeadsByUpdatedBy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+// GetTotalExpectedRevenue handles total expected revenue retrieval
+func (h *LeadHandler) GetTotalExpectedRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
-	updatedBy, err := uuid.Parse(ps.ByName("updatedBy"))
-	if err != nil {
-		http.Error(w, "Invalid updated by ID", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByUpdatedBy(r.Context(), orgID, updatedBy)
+	totalExpectedRevenue, err := h.leadService.GetLeadTotalExpectedRevenue(r.Context(), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
+	json.NewEncoder(w).Encode(map[string]float64{"total_expected_revenue": totalExpectedRevenue})
 }
 
-// GetLeadsByColor handles leads by color retrieval
-func (h *LeadHandler) GetLeadsByColor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+// GetTotalRecurringRevenue handles total recurring revenue retrieval
+func (h *LeadHandler) GetTotalRecurringRevenue(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	color, err := strconv.Atoi(ps.ByName("color"))
-	if err != nil {
-		http.Error(w, "Invalid color", http.StatusBadRequest)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
-	leads, err := h.leadService.GetLeadsByColor(r.Context(), orgID, strconv.Itoa(color))
+	totalRecurringRevenue, err := h.leadService.GetLeadTotalRecurringRevenue(r.Context(), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
+	json.NewEncoder(w).Encode(map[string]float64{"total_recurring_revenue": totalRecurringRevenue})
 }
 
 // GetOverdueLeads handles overdue leads retrieval
 func (h *LeadHandler) GetOverdueLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	leads, err := h.leadService.GetOverdueLeads(r.Context(), orgID)
 	if err != nil {
@@ -1173,12 +770,11 @@ func (h *LeadHandler) GetOverdueLeads(w http.ResponseWriter, r *http.Request, _
 
 // GetHighValueLeads handles high-value leads retrieval
 func (h *LeadHandler) GetHighValueLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	minExpectedRevenue, err := strconv.ParseFloat(r.URL.Query().Get("min_expected_revenue"), 64)
 	if err != nil {
@@ -1197,12 +793,11 @@ func (h *LeadHandler) GetHighValueLeads(w http.ResponseWriter, r *http.Request,
 
 // GetRecentLeads handles recent leads retrieval
 func (h *LeadHandler) GetRecentLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	days, err := strconv.Atoi(r.URL.Query().Get("days"))
 	if err != nil {
@@ -1219,139 +814,13 @@ func (h *LeadHandler) GetRecentLeads(w http.ResponseWriter, r *http.Request, _ h
 	json.NewEncoder(w).Encode(leads)
 }
 
-// GetLeadsByStatus handles leads by status retrieval
-func (h *LeadHandler) GetLeadsByStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	status := ps.ByName("status")
-	if status == "" {
-		http.Error(w, "Invalid status", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByStatus(r.Context(), orgID, status)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByPriority handles leads by priority retrieval
-func (h *LeadHandler) GetLeadsByPriority(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	priority := types.LeadPriority(ps.ByName("priority"))
-	if priority == "" {
-		http.Error(w, "Invalid priority", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByPriority(r.Context(), orgID, priority)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByType handles leads by type retrieval
-func (h *LeadHandler) GetLeadsByType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	leadType := types.LeadType(ps.ByName("leadType"))
-	if leadType == "" {
-		http.Error(w, "Invalid lead type", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByType(r.Context(), orgID, leadType)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByWonStatus handles leads by won status retrieval
-func (h *LeadHandler) GetLeadsByWonStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	wonStatus := types.LeadWonStatus(ps.ByName("wonStatus"))
-	if wonStatus == "" {
-		http.Error(w, "Invalid won status", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByWonStatus(r.Context(), orgID, wonStatus)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
-// GetLeadsByActiveStatus handles leads by active status retrieval
-func (h *LeadHandler) GetLeadsByActiveStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
-	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
-		return
-	}
-
-	active, err := strconv.ParseBool(ps.ByName("active"))
-	if err != nil {
-		http.Error(w, "Invalid active status", http.StatusBadRequest)
-		return
-	}
-
-	leads, err := h.leadService.GetLeadsByActiveStatus(r.Context(), orgID, active)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(leads)
-}
-
 // CountLeadsByStage handles leads count by stage
 func (h *LeadHandler) CountLeadsByStage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByStage(r.Context(), orgID)
 	if err != nil {
@@ -1365,12 +834,11 @@ func (h *LeadHandler) CountLeadsByStage(w http.ResponseWriter, r *http.Request,
 
 // CountLeadsByPriority handles leads count by priority
 func (h *LeadHandler) CountLeadsByPriority(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByPriority(r.Context(), orgID)
 	if err != nil {
@@ -1384,12 +852,11 @@ func (h *LeadHandler) CountLeadsByPriority(w http.ResponseWriter, r *http.Reques
 
 // CountLeadsByType handles leads count by type
 func (h *LeadHandler) CountLeadsByType(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByType(r.Context(), orgID)
 	if err != nil {
@@ -1403,12 +870,11 @@ func (h *LeadHandler) CountLeadsByType(w http.ResponseWriter, r *http.Request, _
 
 // CountLeadsBySource handles leads count by source
 func (h *LeadHandler) CountLeadsBySource(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsBySource(r.Context(), orgID)
 	if err != nil {
@@ -1422,12 +888,11 @@ func (h *LeadHandler) CountLeadsBySource(w http.ResponseWriter, r *http.Request,
 
 // CountLeadsByMedium handles leads count by medium
 func (h *LeadHandler) CountLeadsByMedium(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByMedium(r.Context(), orgID)
 	if err != nil {
@@ -1441,12 +906,11 @@ func (h *LeadHandler) CountLeadsByMedium(w http.ResponseWriter, r *http.Request,
 
 // CountLeadsByCampaign handles leads count by campaign
 func (h *LeadHandler) CountLeadsByCampaign(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByCampaign(r.Context(), orgID)
 	if err != nil {
@@ -1460,12 +924,11 @@ func (h *LeadHandler) CountLeadsByCampaign(w http.ResponseWriter, r *http.Reques
 
 // CountLeadsByTeam handles leads count by team
 func (h *LeadHandler) CountLeadsByTeam(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByTeam(r.Context(), orgID)
 	if err != nil {
@@ -1479,12 +942,11 @@ func (h *LeadHandler) CountLeadsByTeam(w http.ResponseWriter, r *http.Request, _
 
 // CountLeadsByUser handles leads count by user
 func (h *LeadHandler) CountLeadsByUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByUser(r.Context(), orgID)
 	if err != nil {
@@ -1498,12 +960,11 @@ func (h *LeadHandler) CountLeadsByUser(w http.ResponseWriter, r *http.Request, _
 
 // CountLeadsByLostReason handles leads count by lost reason
 func (h *LeadHandler) CountLeadsByLostReason(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByLostReason(r.Context(), orgID)
 	if err != nil {
@@ -1517,12 +978,11 @@ func (h *LeadHandler) CountLeadsByLostReason(w http.ResponseWriter, r *http.Requ
 
 // CountLeadsByWonStatus handles leads count by won status
 func (h *LeadHandler) CountLeadsByWonStatus(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByWonStatus(r.Context(), orgID)
 	if err != nil {
@@ -1536,12 +996,11 @@ func (h *LeadHandler) CountLeadsByWonStatus(w http.ResponseWriter, r *http.Reque
 
 // CountLeadsByCountry handles leads count by country
 func (h *LeadHandler) CountLeadsByCountry(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByCountry(r.Context(), orgID)
 	if err != nil {
@@ -1555,12 +1014,11 @@ func (h *LeadHandler) CountLeadsByCountry(w http.ResponseWriter, r *http.Request
 
 // CountLeadsByState handles leads count by state
 func (h *LeadHandler) CountLeadsByState(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByState(r.Context(), orgID)
 	if err != nil {
@@ -1574,12 +1032,11 @@ func (h *LeadHandler) CountLeadsByState(w http.ResponseWriter, r *http.Request,
 
 // CountLeadsByCity handles leads count by city
 func (h *LeadHandler) CountLeadsByCity(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// Get organization ID from context (set by auth middleware)
-	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	authCtx, ok := auth.RequireAuthContext(w, r)
 	if !ok {
-		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
 		return
 	}
+	orgID := authCtx.OrganizationID
 
 	counts, err := h.leadService.CountLeadsByCity(r.Context(), orgID)
 	if err != nil {
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MarkWon handles closing a lead as won
+func (h *LeadHandler) MarkWon(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadMarkWonRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	outcome, err := h.leadService.MarkWon(r.Context(), orgID, id, req)
+	if err != nil {
+		writeLeadOutcomeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outcome)
+}
+
+// MarkLost handles closing a lead as lost with a required lost reason
+func (h *LeadHandler) MarkLost(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadMarkLostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	outcome, err := h.leadService.MarkLost(r.Context(), orgID, id, req)
+	if err != nil {
+		writeLeadOutcomeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outcome)
+}
+
+// GetProbabilityAccuracy handles the probability accuracy report of closed
+// leads. It takes an optional team_id and a date_from/date_to range
+// (YYYY-MM-DD or RFC 3339) bounding when leads were closed.
+func (h *LeadHandler) GetProbabilityAccuracy(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var filter types.LeadProbabilityAccuracyFilter
+	query := r.URL.Query()
+	if teamID := query.Get("team_id"); teamID != "" {
+		id, err := uuid.Parse(teamID)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		filter.TeamID = &id
+	}
+	for param, target := range map[string]**time.Time{"date_from": &filter.DateFrom, "date_to": &filter.DateTo} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		date, err := parseStageDate(value)
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = &date
+	}
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		http.Error(w, "date_to must be after date_from", http.StatusBadRequest)
+		return
+	}
+
+	accuracy, err := h.leadService.GetProbabilityAccuracy(r.Context(), orgID, filter)
+	if err != nil {
+		writeLeadOutcomeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accuracy)
+}
+
+func writeLeadOutcomeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrLostReasonRequired), errors.Is(err, types.ErrInvalidLeadOutcome):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrLeadClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ComparePipeline handles the week over week comparison of the pipeline
+// snapshots, per team and stage
+func (h *LeadHandler) ComparePipeline(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	query := r.URL.Query()
+	var week *time.Time
+	if value := query.Get("week"); value != "" {
+		date, err := parseStageDate(value)
+		if err != nil {
+			http.Error(w, "Invalid week", http.StatusBadRequest)
+			return
+		}
+		week = &date
+	}
+	var teamID *uuid.UUID
+	if value := query.Get("team_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamID = &id
+	}
+
+	comparison, err := h.leadService.ComparePipeline(r.Context(), orgID, week, teamID)
+	if err != nil {
+		writePipelineSnapshotError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+func writePipelineSnapshotError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrNoPipelineSnapshot), errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
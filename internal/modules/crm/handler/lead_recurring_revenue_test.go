@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+)
+
+// listedLeads serves a fixed set of leads from FindAll
+type listedLeads struct {
+	types.LeadRepository
+	leads  []*types.Lead
+	filter types.LeadFilter
+}
+
+func (r *listedLeads) FindAll(_ context.Context, filter types.LeadFilter) ([]*types.Lead, error) {
+	r.filter = filter
+	return r.leads, nil
+}
+
+func TestGetRecurringRevenueReport(t *testing.T) {
+	orgID, teamID := uuid.New(), uuid.New()
+	qualified, proposal := uuid.New(), uuid.New()
+	march := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)
+	lost, lostRevenue := types.LeadWonStatusLost, 500.0
+	lead := func(stageID *uuid.UUID, revenue float64, plan string, probability int, deadline *time.Time) *types.Lead {
+		return &types.Lead{StageID: stageID, TeamID: &teamID, RecurringRevenue: &revenue, RecurringPlan: &plan,
+			Probability: probability, DateDeadline: deadline, Status: types.LeadStatusInProgress}
+	}
+	repo := &listedLeads{leads: []*types.Lead{
+		lead(&qualified, 1200, "Yearly", 50, &april),
+		lead(&proposal, 300, "monthly", 100, &march),
+		lead(&proposal, 600, "quarterly", 50, nil),
+		lead(nil, 90, "fortnightly", 50, nil),
+		{RecurringRevenue: &lostRevenue, WonStatus: &lost},
+		{Name: "One-off deal"},
+	}}
+	router := httprouter.New()
+	NewLeadHandler(service.NewLeadService(repo, nil, nil, nil)).RegisterRoutes(router)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/leads/recurring-revenue-report?team_id="+teamID.String(), nil)
+	r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: orgID}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, &teamID, repo.filter.TeamID)
+
+	var report types.RecurringRevenueReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+
+	// 100 + 300 + 200 a month; the lost lead and the unknown plan are left out
+	assert.Equal(t, types.RecurringRevenue{Leads: 3, MRR: 600, ARR: 7200, WeightedMRR: 450, WeightedARR: 5400}, report.Total)
+	assert.Equal(t, map[string]int{"fortnightly": 1}, report.UnknownPlans)
+
+	require.Len(t, report.ByStage, 2)
+	assert.Equal(t, &proposal, report.ByStage[0].StageID)
+	assert.Equal(t, 500.0, report.ByStage[0].MRR)
+	assert.Equal(t, &qualified, report.ByStage[1].StageID)
+
+	require.Len(t, report.ByTeam, 1)
+	assert.Equal(t, 600.0, report.ByTeam[0].MRR)
+
+	require.Len(t, report.ByCloseMonth, 3)
+	assert.Equal(t, []string{"2025-03", "2025-04", ""},
+		[]string{report.ByCloseMonth[0].Month, report.ByCloseMonth[1].Month, report.ByCloseMonth[2].Month})
+	assert.Equal(t, 200.0, report.ByCloseMonth[2].MRR)
+}
+
+// aprilYear is the calendar of fiscal years starting in April
+type aprilYear struct{}
+
+func (aprilYear) Find(context.Context, uuid.UUID) (*fiscal.Calendar, error) {
+	return &fiscal.Calendar{StartMonth: time.April, Pattern: fiscal.PatternMonthly}, nil
+}
+
+func TestGetRecurringRevenueReportByFiscalPeriod(t *testing.T) {
+	march := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)
+	revenue, plan := 100.0, "monthly"
+	repo := &listedLeads{leads: []*types.Lead{
+		{RecurringRevenue: &revenue, RecurringPlan: &plan, Probability: 100, DateDeadline: &march},
+		{RecurringRevenue: &revenue, RecurringPlan: &plan, Probability: 100, DateClosed: &april},
+		{RecurringRevenue: &revenue, RecurringPlan: &plan, Probability: 100},
+	}}
+	leadService := service.NewLeadService(repo, nil, nil, nil)
+	leadService.SetFiscalPeriods(fiscal.NewPeriods(aprilYear{}))
+	router := httprouter.New()
+	NewLeadHandler(leadService).RegisterRoutes(router)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/leads/recurring-revenue-report"+query, nil)
+		r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: uuid.New()}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report types.RecurringRevenueReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Len(t, report.ByClosePeriod, 3)
+	assert.Equal(t, []string{"FY2025-P12", "FY2026-P01", ""},
+		[]string{report.ByClosePeriod[0].Period, report.ByClosePeriod[1].Period, report.ByClosePeriod[2].Period})
+
+	// FY2026 starts in April 2025
+	w = get("?period=FY2026-Q1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	report = types.RecurringRevenueReport{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, "FY2026-Q1", report.FiscalPeriod)
+	assert.Equal(t, 1, report.Total.Leads)
+	require.Len(t, report.ByClosePeriod, 1)
+	assert.Equal(t, "FY2026-P01", report.ByClosePeriod[0].Period)
+
+	assert.Equal(t, http.StatusBadRequest, get("?period=Q1").Code)
+}
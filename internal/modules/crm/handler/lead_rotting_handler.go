@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetRotationStats handles the per stage metrics of rotated rotting leads
+func (h *LeadHandler) GetRotationStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var filter types.LeadRotationStatsFilter
+	query := r.URL.Query()
+	if !parseSLADateRange(w, query.Get("date_from"), query.Get("date_to"), &filter.DateFrom, &filter.DateTo) {
+		return
+	}
+
+	stats, err := h.leadService.GetRotationStats(r.Context(), orgID, filter)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routeBySegment serves a path segment that is either one of the named
+// routes or the value of the param wildcard. httprouter does not allow a
+// static segment beside a wildcard, so routes such as /leads/count and
+// /leads/:id are registered once and told apart here. A nil fallback
+// answers 404 for values that are not named routes.
+func routeBySegment(param string, routes map[string]httprouter.Handle, fallback httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if route, ok := routes[ps.ByName(param)]; ok {
+			route(w, r, ps)
+			return
+		}
+		if fallback == nil {
+			http.NotFound(w, r)
+			return
+		}
+		fallback(w, r, ps)
+	}
+}
+
+// leadsPath is the lead collection, under which the deprecated
+// by-<filter> routes live
+const leadsPath = "/api/v1/leads"
+
+// legacyLeadFilterParams maps each deprecated GET /api/v1/leads/by-<filter>/:value
+// route to the GET /api/v1/leads query parameter that replaced it
+var legacyLeadFilterParams = map[string]string{
+	"by-contact":       "contact_id",
+	"by-user":          "user_id",
+	"by-team":          "team_id",
+	"by-stage":         "stage_id",
+	"by-source":        "source_id",
+	"by-campaign":      "campaign_id",
+	"by-medium":        "medium_id",
+	"by-tag":           "tag_ids",
+	"by-company":       "company_id",
+	"by-country":       "country_id",
+	"by-state":         "state_id",
+	"by-city":          "city",
+	"by-lost-reason":   "lost_reason_id",
+	"by-created-by":    "created_by",
+	"by-updated-by":    "updated_by",
+	"by-color":         "color",
+	"by-status":        "status",
+	"by-priority":      "priority",
+	"by-type":          "lead_type",
+	"by-won-status":    "won_status",
+	"by-active-status": "active",
+}
+
+// legacyLeadFilterRoutes serves the deprecated by-<filter> routes as the
+// equivalent lead listing, marked deprecated and linking to its successor.
+// They cannot be registered on the router beside /leads/:id, so they are
+// answered in place of a 404; any other unmatched request goes to next.
+func legacyLeadFilterRoutes(list httprouter.Handle, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if rest, ok := strings.CutPrefix(r.URL.Path, leadsPath+"/"); ok {
+				route, value, _ := strings.Cut(rest, "/")
+				if param, ok := legacyLeadFilterParams[route]; ok && value != "" && !strings.Contains(value, "/") {
+					query := url.Values{param: {value}}
+					w.Header().Set("Deprecation", "true")
+					w.Header().Set("Link", "<"+leadsPath+"?"+query.Encode()+`>; rel="successor-version"`)
+
+					r = r.Clone(r.Context())
+					r.URL.RawQuery = query.Encode()
+					list(w, r, nil)
+					return
+				}
+			}
+		}
+		if next == nil {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+)
+
+func TestLeadRoutesRegisterWithoutConflicts(t *testing.T) {
+	router := httprouter.New()
+	require.NotPanics(t, func() {
+		NewLeadHandler(nil).RegisterRoutes(router)
+		NewLeadEmailHandler(nil).RegisterRoutes(router)
+		NewSchedulingLinkHandler(nil).RegisterRoutes(router)
+	})
+
+	handle, ps, _ := router.Lookup(http.MethodGet, "/api/v1/leads/count")
+	require.NotNil(t, handle)
+	assert.Equal(t, "count", ps.ByName("id"))
+
+	handle, ps, _ = router.Lookup(http.MethodGet, "/api/v1/leads/board")
+	require.NotNil(t, handle)
+	assert.Equal(t, "board", ps.ByName("id"))
+
+	handle, ps, _ = router.Lookup(http.MethodPost, "/api/v1/leads/search")
+	require.NotNil(t, handle)
+	assert.Equal(t, "search", ps.ByName("id"))
+
+	handle, ps, _ = router.Lookup(http.MethodGet, "/api/v1/leads/trash")
+	require.NotNil(t, handle)
+	assert.Equal(t, "trash", ps.ByName("id"))
+	handle, _, _ = router.Lookup(http.MethodPost, "/api/v1/leads/"+uuid.NewString()+"/restore")
+	assert.NotNil(t, handle)
+
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/leads/"+uuid.NewString()+"/emails")
+	assert.NotNil(t, handle)
+
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/leads/"+uuid.NewString()+"/cadences")
+	assert.NotNil(t, handle)
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/lead-cadence-performance")
+	assert.NotNil(t, handle)
+
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/leads/"+uuid.NewString()+"/history")
+	assert.NotNil(t, handle)
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/lead-audit-log")
+	assert.NotNil(t, handle)
+
+	handle, _, _ = router.Lookup(http.MethodGet, "/api/v1/leads/"+uuid.NewString()+"/bookings")
+	assert.NotNil(t, handle)
+	handle, ps, _ = router.Lookup(http.MethodGet, "/public/v1/booking/abc123/slots")
+	require.NotNil(t, handle)
+	assert.Equal(t, "abc123", ps.ByName("token"))
+}
+
+func TestRouteBySegment(t *testing.T) {
+	var served string
+	handle := routeBySegment("id", map[string]httprouter.Handle{
+		"count": func(http.ResponseWriter, *http.Request, httprouter.Params) { served = "count" },
+	}, func(http.ResponseWriter, *http.Request, httprouter.Params) { served = "lead" })
+
+	handle(httptest.NewRecorder(), nil, httprouter.Params{{Key: "id", Value: "count"}})
+	assert.Equal(t, "count", served)
+	handle(httptest.NewRecorder(), nil, httprouter.Params{{Key: "id", Value: uuid.NewString()}})
+	assert.Equal(t, "lead", served)
+
+	w := httptest.NewRecorder()
+	routeBySegment("id", nil, nil)(w, httptest.NewRequest(http.MethodPost, "/api/v1/leads/x", nil), httprouter.Params{{Key: "id", Value: "x"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLegacyLeadFilterRoutes(t *testing.T) {
+	var query url.Values
+	list := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		query = r.URL.Query()
+	}
+	handler := legacyLeadFilterRoutes(list, nil)
+
+	contactID := uuid.NewString()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/leads/by-contact/"+contactID, nil))
+	assert.Equal(t, url.Values{"contact_id": {contactID}}, query)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "</api/v1/leads?contact_id="+contactID+`>; rel="successor-version"`, w.Header().Get("Link"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/leads/by-city/San%20Jose", nil))
+	assert.Equal(t, url.Values{"city": {"San Jose"}}, query)
+
+	for _, path := range []string{"/api/v1/leads/by-unknown/1", "/api/v1/leads/by-contact/", "/api/v1/contacts/by-contact/1"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestParseLeadFilter(t *testing.T) {
+	userID, tagID, otherTagID, legacyTagID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	filter, err := parseLeadFilter(url.Values{
+		"created_by": {userID.String()},
+		"updated_by": {userID.String()},
+		"tag_ids":    {tagID.String() + "," + otherTagID.String()},
+		"tag_id":     {legacyTagID.String()},
+		"color":      {"3"},
+		"city":       {"Lyon"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &userID, filter.CreatedBy)
+	assert.Equal(t, &userID, filter.UpdatedBy)
+	assert.Equal(t, []uuid.UUID{tagID, otherTagID, legacyTagID}, filter.TagIDs)
+	assert.Equal(t, "3", *filter.Color)
+	assert.Equal(t, "Lyon", *filter.City)
+
+	// A malformed filter is rejected rather than dropped, which would list every lead
+	for _, query := range []url.Values{
+		{"contact_id": {"not-a-uuid"}},
+		{"probability_min": {"high"}},
+		{"active": {"maybe"}},
+		{"status": {"unknown"}},
+		{"tag_ids": {uuid.NewString() + ",not-a-uuid"}},
+	} {
+		_, err := parseLeadFilter(query)
+		assert.Error(t, err, query.Encode())
+	}
+}
+
+func TestLeadListRejectsUnknownStatus(t *testing.T) {
+	router := httprouter.New()
+	NewLeadHandler(nil).RegisterRoutes(router)
+
+	for _, path := range []string{"/api/v1/leads?status=open", "/api/v1/leads/count?status=open"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r = r.WithContext(auth.WithAuthContext(r.Context(), &auth.AuthContext{OrganizationID: uuid.New()}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), `invalid status: "open"`, path)
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SearchLeads handles full-text lead search. q is required; stage_id and
+// priority narrow the hits, and limit and offset page through them.
+func (h *LeadHandler) SearchLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	q := r.URL.Query()
+	req := types.LeadSearchRequest{Query: q.Get("q")}
+	if stageID := q.Get("stage_id"); stageID != "" {
+		parsedID, err := uuid.Parse(stageID)
+		if err != nil {
+			http.Error(w, "Invalid stage_id", http.StatusBadRequest)
+			return
+		}
+		req.StageID = &parsedID
+	}
+	if priority := q.Get("priority"); priority != "" {
+		typedPriority := types.LeadPriority(priority)
+		req.Priority = &typedPriority
+	}
+	if limit := q.Get("limit"); limit != "" {
+		val, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		req.Limit = val
+	}
+	if offset := q.Get("offset"); offset != "" {
+		val, err := strconv.Atoi(offset)
+		if err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		req.Offset = val
+	}
+
+	result, err := h.leadService.SearchLeads(r.Context(), orgID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrInvalidLeadSearch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "permission denied"):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// LeadShareHandler serves the share links of lead views, and the public
+// read-only views they lead to
+type LeadShareHandler struct {
+	service *service.LeadShareService
+}
+
+func NewLeadShareHandler(service *service.LeadShareService) *LeadShareHandler {
+	return &LeadShareHandler{
+		service: service,
+	}
+}
+
+func (h *LeadShareHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/lead-share-links", h.ListLinks)
+	router.POST("/api/v1/lead-share-links", h.CreateLink)
+	router.DELETE("/api/v1/lead-share-links/:id", h.RevokeLink)
+
+	// Public: the shared view, reached with the link token
+	router.GET(service.LeadSharePath+":token", h.ViewLeads)
+}
+
+// ListLinks handles the organization's share links, live or not
+func (h *LeadShareHandler) ListLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := h.service.ListLinks(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeLeadShareError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, map[string]interface{}{
+		"data": links,
+	})
+}
+
+// CreateLink handles sharing a lead view; the response holds the only copy
+// of the link's path
+func (h *LeadShareHandler) CreateLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.LeadShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.CreateLink(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeLeadShareError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusCreated, link)
+}
+
+// RevokeLink handles ending a share link; the link is kept with its views
+func (h *LeadShareHandler) RevokeLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid share link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.RevokeLink(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeLeadShareError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, link)
+}
+
+// ViewLeads handles a page of a shared view, with limit and offset query
+// parameters
+func (h *LeadShareHandler) ViewLeads(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	view, err := h.service.ViewLeads(r.Context(), ps.ByName("token"), limit, offset)
+	if err != nil {
+		if errors.Is(err, types.ErrLeadShareLinkNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "This view could not be loaded", http.StatusInternalServerError)
+		return
+	}
+
+	// Shared views are not to be kept by caches or indexed
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writeContactJSON(w, http.StatusOK, view)
+}
+
+func writeLeadShareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadShareLink), errors.Is(err, query.ErrInvalidQuery), errors.Is(err, types.ErrInvalidLeadSort):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+// shareLinks keeps share links in memory
+type shareLinks struct {
+	links []types.LeadShareLink
+	now   time.Time
+}
+
+func (s *shareLinks) CreateShareLink(_ context.Context, link types.LeadShareLink) (*types.LeadShareLink, error) {
+	s.links = append(s.links, link)
+	return &link, nil
+}
+
+func (s *shareLinks) ListShareLinks(context.Context, uuid.UUID) ([]types.LeadShareLink, error) {
+	return s.links, nil
+}
+
+func (s *shareLinks) RevokeShareLink(_ context.Context, orgID, id uuid.UUID, revokedBy *uuid.UUID, at time.Time) (*types.LeadShareLink, error) {
+	for i := range s.links {
+		if s.links[i].ID == id && s.links[i].OrganizationID == orgID {
+			s.links[i].RevokedAt, s.links[i].RevokedBy = &at, revokedBy
+			return &s.links[i], nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (s *shareLinks) ViewShareLink(_ context.Context, tokenHash string, at time.Time) (*types.LeadShareLink, error) {
+	for i := range s.links {
+		link := &s.links[i]
+		if link.TokenHash == tokenHash && link.RevokedAt == nil && link.ExpiresAt.After(s.now) {
+			link.ViewCount++
+			return link, nil
+		}
+	}
+	return nil, types.ErrLeadShareLinkNotFound
+}
+
+// sharedLeads lists fixed leads, recording the filter they were listed with
+// and whether they were counted apart from the page
+type sharedLeads struct {
+	types.LeadRepository
+	leads   []*types.Lead
+	filter  types.LeadFilter
+	counted bool
+}
+
+func (r *sharedLeads) FindPage(_ context.Context, filter types.LeadFilter, withTotal bool) (*types.LeadPage, error) {
+	r.filter = filter
+	page := &types.LeadPage{Data: r.leads, Limit: filter.Limit, Offset: filter.Offset}
+	if withTotal {
+		total := len(r.leads)
+		page.Total = &total
+	}
+	return page, nil
+}
+
+func (r *sharedLeads) Count(context.Context, types.LeadFilter) (int, error) {
+	r.counted = true
+	return len(r.leads), nil
+}
+
+func TestSharedLeadViewShowsOnlyTheLinkFields(t *testing.T) {
+	orgID, userID := uuid.New(), uuid.New()
+	email, revenue := "cfo@example.test", 25000.0
+	leads := &sharedLeads{leads: []*types.Lead{{ID: uuid.New(), OrganizationID: orgID, Name: "Acme renewal", Email: &email,
+		ExpectedRevenue: &revenue, Priority: types.LeadPriorityHigh, CustomFields: []byte(`{"region": "emea"}`)}}}
+	links := &shareLinks{now: time.Now()}
+	router := httprouter.New()
+	NewLeadShareHandler(service.NewLeadShareService(links, service.NewLeadService(leads, nil, nil, nil), contextCaller{}, nil)).RegisterRoutes(router)
+
+	for _, body := range []string{
+		`{"name": ""}`,
+		`{"name": "Q3", "fields": ["name", "metadata"]}`,
+		`{"name": "Q3", "fields": ["custom.region"]}`,
+		`{"name": "Q3", "query": {"field": "budget", "op": "gt", "value": 1}}`,
+		`{"name": "Q3", "expires_at": "` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}`,
+		`{"name": "Q3", "expires_at": "` + time.Now().Add(100*24*time.Hour).Format(time.RFC3339) + `"}`,
+	} {
+		w := serveAs(router, orgID, userID, http.MethodPost, "/api/v1/lead-share-links", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := serveAs(router, orgID, userID, http.MethodPost, "/api/v1/lead-share-links", `{
+		"name": "High priority pipeline",
+		"query": {"field": "priority", "op": "in", "value": ["high", "urgent"]},
+		"sort_by": "expected_revenue", "sort_dir": "desc",
+		"fields": ["name", "expected_revenue", "priority", "name"]
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var link types.LeadShareLink
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.Equal(t, []string{"name", "expected_revenue", "priority"}, link.Fields)
+	assert.Equal(t, &userID, link.CreatedBy)
+	assert.WithinDuration(t, time.Now().Add(service.DefaultLeadShareLifetime), link.ExpiresAt, time.Minute)
+	require.Regexp(t, `^/public/v1/lead-views/[0-9a-f]{48}$`, link.SharePath)
+	assert.NotContains(t, link.SharePath, links.links[0].TokenHash)
+
+	// The public view needs no login and leaves out the other fields
+	w = servePublic(router, http.MethodGet, link.SharePath+"?limit=500")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var view types.PublicLeadView
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &view))
+	assert.Equal(t, "High priority pipeline", view.Name)
+	assert.Equal(t, 1, view.Total)
+	assert.False(t, leads.counted, "the page is counted in the same query")
+	assert.Equal(t, 100, view.Limit)
+	assert.Equal(t, []map[string]interface{}{{"name": "Acme renewal", "expected_revenue": 25000.0, "priority": "high"}}, view.Data)
+	assert.NotContains(t, w.Body.String(), email)
+	assert.Equal(t, orgID, leads.filter.OrganizationID)
+	assert.Equal(t, types.LeadSortField("expected_revenue"), leads.filter.SortBy)
+	assert.NotNil(t, leads.filter.Condition)
+	assert.Equal(t, 1, links.links[0].ViewCount)
+
+	w = serveAs(router, orgID, userID, http.MethodDelete, "/api/v1/lead-share-links/"+link.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = servePublic(router, http.MethodGet, link.SharePath)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestSharedLeadViewExpires(t *testing.T) {
+	links := &shareLinks{now: time.Now()}
+	router := httprouter.New()
+	NewLeadShareHandler(service.NewLeadShareService(links, service.NewLeadService(&sharedLeads{}, nil, nil, nil), contextCaller{}, nil)).RegisterRoutes(router)
+
+	expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+	w := serveAs(router, uuid.New(), uuid.New(), http.MethodPost, "/api/v1/lead-share-links", `{"name": "Today", "expires_at": "`+expires+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var link types.LeadShareLink
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.Equal(t, types.DefaultLeadShareFields, link.Fields)
+
+	w = servePublic(router, http.MethodGet, link.SharePath)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	links.now = links.now.Add(2 * time.Hour)
+	w = servePublic(router, http.MethodGet, link.SharePath)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
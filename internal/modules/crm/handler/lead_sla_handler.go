@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetSLABreaches handles retrieval of the leads that missed their response
+// target. It takes optional team_id, assigned_to, open=true for leads still
+// waiting for a response, a date_from/date_to range (YYYY-MM-DD or RFC 3339)
+// bounding when the breach happened, and limit/offset.
+func (h *LeadHandler) GetSLABreaches(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var filter types.LeadSLABreachFilter
+	query := r.URL.Query()
+	for param, target := range map[string]**uuid.UUID{"team_id": &filter.TeamID, "assigned_to": &filter.AssignedTo} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = &id
+	}
+	if value := query.Get("open"); value != "" {
+		open, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid open", http.StatusBadRequest)
+			return
+		}
+		filter.Open = open
+	}
+	for param, target := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+	if !parseSLADateRange(w, query.Get("date_from"), query.Get("date_to"), &filter.DateFrom, &filter.DateTo) {
+		return
+	}
+
+	breaches, err := h.leadService.GetSLABreaches(r.Context(), orgID, filter)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breaches)
+}
+
+// GetSLACompliance handles retrieval of the per-team SLA compliance for the
+// clocks started in an optional date_from/date_to range
+func (h *LeadHandler) GetSLACompliance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var filter types.LeadSLAComplianceFilter
+	query := r.URL.Query()
+	if !parseSLADateRange(w, query.Get("date_from"), query.Get("date_to"), &filter.DateFrom, &filter.DateTo) {
+		return
+	}
+
+	compliance, err := h.leadService.GetSLACompliance(r.Context(), orgID, filter)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(compliance)
+}
+
+// ListSLAPolicies handles SLA policy listing
+func (h *LeadHandler) ListSLAPolicies(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	policies, err := h.leadService.ListSLAPolicies(r.Context(), orgID)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// CreateSLAPolicy handles SLA policy creation
+func (h *LeadHandler) CreateSLAPolicy(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var req types.LeadSLAPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.leadService.CreateSLAPolicy(r.Context(), orgID, req)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateSLAPolicy handles SLA policy updates
+func (h *LeadHandler) UpdateSLAPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid SLA policy ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadSLAPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.leadService.UpdateSLAPolicy(r.Context(), orgID, id, req)
+	if err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// DeleteSLAPolicy handles SLA policy deletion
+func (h *LeadHandler) DeleteSLAPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid SLA policy ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.leadService.DeleteSLAPolicy(r.Context(), orgID, id); err != nil {
+		writeLeadSLAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseSLADateRange parses the optional date range of an SLA report, writing
+// a bad request and returning false when it is invalid
+func parseSLADateRange(w http.ResponseWriter, from, to string, dateFrom, dateTo **time.Time) bool {
+	for param, value := range map[string]string{"date_from": from, "date_to": to} {
+		if value == "" {
+			continue
+		}
+		date, err := parseStageDate(value)
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return false
+		}
+		if param == "date_from" {
+			*dateFrom = &date
+		} else {
+			*dateTo = &date
+		}
+	}
+	if *dateFrom != nil && *dateTo != nil && !(*dateTo).After(**dateFrom) {
+		http.Error(w, "date_to must be after date_from", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeLeadSLAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidLeadSLAPolicy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrLeadSLAPolicyExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
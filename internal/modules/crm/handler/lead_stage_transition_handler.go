@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MoveStage handles moving a lead to another stage
+func (h *LeadHandler) MoveStage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.LeadMoveStageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.StageID == uuid.Nil {
+		http.Error(w, "stage_id is required", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.leadService.MoveStage(r.Context(), orgID, id, req)
+	if err != nil {
+		writeStageTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+// GetStageHistory handles lead stage history retrieval
+func (h *LeadHandler) GetStageHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.leadService.GetStageHistory(r.Context(), orgID, id)
+	if err != nil {
+		writeStageTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetStageVelocity handles stage velocity retrieval. It takes an optional
+// team_id and a date_from/date_to range (YYYY-MM-DD or RFC 3339) bounding
+// when leads left each stage.
+func (h *LeadHandler) GetStageVelocity(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var filter types.StageVelocityFilter
+	query := r.URL.Query()
+	if teamID := query.Get("team_id"); teamID != "" {
+		id, err := uuid.Parse(teamID)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		filter.TeamID = &id
+	}
+	for param, target := range map[string]**time.Time{"date_from": &filter.DateFrom, "date_to": &filter.DateTo} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		date, err := parseStageDate(value)
+		if err != nil {
+			http.Error(w, "Invalid "+param, http.StatusBadRequest)
+			return
+		}
+		*target = &date
+	}
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		http.Error(w, "date_to must be after date_from", http.StatusBadRequest)
+		return
+	}
+
+	velocity, err := h.leadService.GetStageVelocity(r.Context(), orgID, filter)
+	if err != nil {
+		writeStageTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(velocity)
+}
+
+// GetStageTransitions handles retrieval of the stage transitions that apply
+// to a team's leads, or the organization default without a team_id
+func (h *LeadHandler) GetStageTransitions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var teamID *uuid.UUID
+	if value := r.URL.Query().Get("team_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamID = &id
+	}
+
+	transitions, err := h.leadService.GetStageTransitions(r.Context(), orgID, teamID)
+	if err != nil {
+		writeStageTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transitions)
+}
+
+// SetStageTransitions handles replacing the stage transitions of a pipeline
+func (h *LeadHandler) SetStageTransitions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var req types.LeadStageTransitions
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	transitions, err := h.leadService.SetStageTransitions(r.Context(), orgID, req)
+	if err != nil {
+		writeStageTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transitions)
+}
+
+func parseStageDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeStageTransitionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrStageTransitionNotAllowed):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, types.ErrLeadStageChanged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
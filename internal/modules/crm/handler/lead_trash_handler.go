@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListDeletedLeads handles GET /api/v1/leads/trash, answering a page of
+// soft-deleted leads as {data, total, limit, offset}
+func (h *LeadHandler) ListDeletedLeads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	var limit, offset int
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if val, err := strconv.Atoi(value); err == nil {
+			limit = val
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if val, err := strconv.Atoi(value); err == nil {
+			offset = val
+		}
+	}
+
+	page, err := h.leadService.ListDeletedLeads(r.Context(), orgID, limit, offset)
+	if err != nil {
+		writeLeadTrashError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// RestoreLead handles POST /api/v1/leads/:id/restore
+func (h *LeadHandler) RestoreLead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+	orgID := authCtx.OrganizationID
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	lead, err := h.leadService.RestoreLead(r.Context(), orgID, id)
+	if err != nil {
+		writeLeadTrashError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lead)
+}
+
+func writeLeadTrashError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/google/uuid"
-	"github.com/julienschmidt/httprouter"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 type LostReasonHandler struct {
@@ -69,6 +70,13 @@ func (h *LostReasonHandler) ListLostReasons(w http.ResponseWriter, r *http.Reque
 	// Parse query parameters
 	filter := types.LostReasonFilter{}
 
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
+
 	if name := r.URL.Query().Get("name"); name != "" {
 		filter.Name = &name
 	}
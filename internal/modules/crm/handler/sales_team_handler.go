@@ -5,10 +5,11 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/google/uuid"
-	"github.com/julienschmidt/httprouter"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 type SalesTeamHandler struct {
@@ -70,6 +71,13 @@ func (h *SalesTeamHandler) ListSalesTeams(w http.ResponseWriter, r *http.Request
 	// Parse query parameters
 	filter := types.SalesTeamFilter{}
 
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.MatchMode = matchMode
+
 	if companyID := r.URL.Query().Get("company_id"); companyID != "" {
 		if id, err := uuid.Parse(companyID); err == nil {
 			filter.CompanyID = &id
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type SchedulingLinkHandler struct {
+	service *service.SchedulingLinkService
+}
+
+func NewSchedulingLinkHandler(service *service.SchedulingLinkService) *SchedulingLinkHandler {
+	return &SchedulingLinkHandler{
+		service: service,
+	}
+}
+
+func (h *SchedulingLinkHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/scheduling-links", h.ListLinks)
+	router.POST("/api/v1/scheduling-links", h.CreateLink)
+	router.GET("/api/v1/scheduling-links/:id", h.GetLink)
+	router.PUT("/api/v1/scheduling-links/:id", h.UpdateLink)
+	router.DELETE("/api/v1/scheduling-links/:id", h.DeleteLink)
+	router.GET("/api/v1/leads/:id/bookings", h.ListLeadBookings)
+
+	// Public: the booking page, reached with the link token
+	router.GET(service.SchedulingLinkPath+":token", h.GetPublicLink)
+	router.GET(service.SchedulingLinkPath+":token/slots", h.ListSlots)
+	router.POST(service.SchedulingLinkPath+":token", h.Book)
+}
+
+func (h *SchedulingLinkHandler) ListLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := h.service.ListLinks(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+func (h *SchedulingLinkHandler) GetLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid scheduling link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.GetLink(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *SchedulingLinkHandler) CreateLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SchedulingLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.CreateLink(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *SchedulingLinkHandler) UpdateLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid scheduling link ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SchedulingLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.UpdateLink(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *SchedulingLinkHandler) DeleteLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid scheduling link ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteLink(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLeadBookings handles retrieval of the meetings a lead booked
+func (h *SchedulingLinkHandler) ListLeadBookings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
+		return
+	}
+
+	bookings, err := h.service.ListLeadBookings(r.Context(), authCtx.OrganizationID, leadID)
+	if err != nil {
+		writeSchedulingLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookings)
+}
+
+// GetPublicLink handles the booking page's name, length and time zone
+func (h *SchedulingLinkHandler) GetPublicLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	link, err := h.service.GetPublicLink(r.Context(), ps.ByName("token"))
+	if err != nil {
+		writePublicBookingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// ListSlots handles the free slots of a booking page between the RFC 3339
+// from and to query parameters
+func (h *SchedulingLinkHandler) ListSlots(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := h.service.ListSlots(r.Context(), ps.ByName("token"), from, to)
+	if err != nil {
+		writePublicBookingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// Book handles a prospect's booking. The lead query parameter of the
+// booking page attributes the meeting to that lead.
+func (h *SchedulingLinkHandler) Book(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req types.LeadBookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if raw := r.URL.Query().Get("lead"); raw != "" {
+		if leadID, err := uuid.Parse(raw); err == nil {
+			req.LeadID = &leadID
+		}
+	}
+
+	confirmation, err := h.service.Book(r.Context(), ps.ByName("token"), req)
+	if err != nil {
+		writePublicBookingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(confirmation)
+}
+
+func writeSchedulingLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidSchedulingLink):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows), strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writePublicBookingError answers a prospect without exposing internal errors
+func writePublicBookingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrSchedulingLinkNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, types.ErrInvalidBooking):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrSlotUnavailable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Booking could not be processed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+// bookingLinks serves one team link and records the booking attempts,
+// failing the hosts in taken as if another booking won the slot
+type bookingLinks struct {
+	types.SchedulingLinkRepository
+	link     types.SchedulingLink
+	counts   map[uuid.UUID]int
+	taken    map[uuid.UUID]bool
+	attempts []uuid.UUID
+	booked   *types.LeadBooking
+	activity types.Activity
+}
+
+func (r *bookingLinks) FindLinkByToken(_ context.Context, token string) (*types.SchedulingLink, error) {
+	if token != r.link.Token {
+		return nil, types.ErrSchedulingLinkNotFound
+	}
+	link := r.link
+	return &link, nil
+}
+
+func (r *bookingLinks) BookingCounts(context.Context, uuid.UUID) (map[uuid.UUID]int, error) {
+	return r.counts, nil
+}
+
+func (r *bookingLinks) CreateBooking(_ context.Context, booking types.LeadBooking, activity types.Activity, _ time.Duration) (*types.LeadBooking, error) {
+	r.attempts = append(r.attempts, booking.HostUserID)
+	if r.taken[booking.HostUserID] {
+		return nil, types.ErrSlotUnavailable
+	}
+	r.booked, r.activity = &booking, activity
+	return &booking, nil
+}
+
+type bookingTeams struct {
+	types.SalesTeamRepository
+	team types.SalesTeam
+}
+
+func (r *bookingTeams) FindByID(context.Context, uuid.UUID) (*types.SalesTeam, error) {
+	return &r.team, nil
+}
+
+type trackedLeads struct {
+	types.LeadRepository
+	lead types.Lead
+}
+
+func (r *trackedLeads) FindByID(_ context.Context, id uuid.UUID) (*types.Lead, error) {
+	lead := r.lead
+	return &lead, nil
+}
+
+func TestBookTracksLeadAndBalancesHosts(t *testing.T) {
+	orgID, teamID, leadID := uuid.New(), uuid.New(), uuid.New()
+	busyMember, quietMember, takenMember := uuid.New(), uuid.New(), uuid.New()
+	links := &bookingLinks{
+		link: types.SchedulingLink{
+			ID: uuid.New(), OrganizationID: orgID, Name: "Demo", Token: "demo", TeamID: &teamID,
+			DurationMinutes: 30, StepMinutes: 30, HorizonDays: 30, Active: true,
+		},
+		counts: map[uuid.UUID]int{busyMember: 5, quietMember: 2, takenMember: 0},
+		taken:  map[uuid.UUID]bool{takenMember: true},
+	}
+	teams := &bookingTeams{team: types.SalesTeam{
+		OrganizationID: orgID, MemberIDs: []uuid.UUID{busyMember, quietMember, takenMember}, IsActive: true,
+	}}
+	leads := &trackedLeads{lead: types.Lead{ID: leadID, OrganizationID: orgID}}
+	svc := service.NewSchedulingLinkService(links, leads, teams, nil, nil, nil, nil)
+
+	router := httprouter.New()
+	NewSchedulingLinkHandler(svc).RegisterRoutes(router)
+
+	starts := time.Now().UTC().Truncate(24 * time.Hour).Add(3*24*time.Hour + 10*time.Hour)
+	body, err := json.Marshal(types.LeadBookingRequest{StartsAt: starts, Name: "Ada", Email: "Ada@Example.com"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/public/v1/booking/demo?lead="+leadID.String(), bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// The least booked member lost the slot meanwhile, so the next one got it
+	assert.Equal(t, []uuid.UUID{takenMember, quietMember}, links.attempts)
+	require.NotNil(t, links.booked)
+	assert.Equal(t, leadID, links.booked.LeadID)
+	assert.Equal(t, types.LeadBookingTracking, links.booked.Attribution)
+	assert.Equal(t, "ada@example.com", links.booked.InviteeEmail)
+	assert.Equal(t, starts.Add(30*time.Minute), links.booked.EndsAt)
+	assert.Equal(t, types.ActivityTypeMeeting, links.activity.ActivityType)
+	assert.Equal(t, &quietMember, links.activity.AssignedTo)
+
+	// Off the step grid is not a slot
+	body, err = json.Marshal(types.LeadBookingRequest{StartsAt: starts.Add(10 * time.Minute), Name: "Ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/public/v1/booking/demo", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/v1/booking/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
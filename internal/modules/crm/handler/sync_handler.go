@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// SyncHandler serves the delta sync of the field sales app
+type SyncHandler struct {
+	service *service.SyncService
+}
+
+func NewSyncHandler(service *service.SyncService) *SyncHandler {
+	return &SyncHandler{
+		service: service,
+	}
+}
+
+func (h *SyncHandler) RegisterRoutes(router *httprouter.Router) {
+	router.POST("/api/v1/sync", h.Sync)
+	router.GET("/api/v1/sync/:entity/changes", h.Changes)
+}
+
+// Sync handles one round trip of the app: its offline writes, then the
+// changes of the entities it pulls
+func (h *SyncHandler) Sync(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Sync(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeSyncError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, resp)
+}
+
+// Changes handles a page of an entity's changes, with cursor, fields (comma
+// separated) and limit query parameters
+func (h *SyncHandler) Changes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	pull := types.SyncPull{Cursor: q.Get("cursor")}
+	for _, field := range strings.Split(q.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			pull.Fields = append(pull.Fields, field)
+		}
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	page, err := h.service.Changes(r.Context(), authCtx.OrganizationID, types.SyncEntity(ps.ByName("entity")), pull, limit)
+	if err != nil {
+		writeSyncError(w, err)
+		return
+	}
+
+	writeContactJSON(w, http.StatusOK, page)
+}
+
+func writeSyncError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidSync):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
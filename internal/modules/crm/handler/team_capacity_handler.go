@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type TeamCapacityHandler struct {
+	service *service.TeamCapacityService
+}
+
+func NewTeamCapacityHandler(service *service.TeamCapacityService) *TeamCapacityHandler {
+	return &TeamCapacityHandler{
+		service: service,
+	}
+}
+
+func (h *TeamCapacityHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/crm/team-capacity/workload", h.GetWorkload)
+	router.PUT("/api/crm/team-capacity/users/:user_id", h.UpdateCapacity)
+	router.GET("/api/crm/team-capacity/users/:user_id/unavailability", h.ListUnavailability)
+	router.POST("/api/crm/team-capacity/users/:user_id/unavailability", h.ScheduleUnavailability)
+	router.DELETE("/api/crm/team-capacity/users/:user_id/unavailability/:id", h.DeleteUnavailability)
+}
+
+// GetWorkload handles GET /api/crm/team-capacity/workload?target_model=&team_id=
+func (h *TeamCapacityHandler) GetWorkload(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var teamID *uuid.UUID
+	if raw := r.URL.Query().Get("team_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		teamID = &id
+	}
+
+	workload, err := h.service.GetTeamWorkload(r.Context(), authCtx.OrganizationID, r.URL.Query().Get("target_model"), teamID)
+	if err != nil {
+		writeTeamCapacityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workload)
+}
+
+func (h *TeamCapacityHandler) UpdateCapacity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UserCapacityUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	capacity, err := h.service.UpdateCapacity(r.Context(), authCtx.OrganizationID, userID, req)
+	if err != nil {
+		writeTeamCapacityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(capacity)
+}
+
+func (h *TeamCapacityHandler) ListUnavailability(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	periods, err := h.service.ListUnavailability(r.Context(), authCtx.OrganizationID, userID)
+	if err != nil {
+		writeTeamCapacityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(periods)
+}
+
+func (h *TeamCapacityHandler) ScheduleUnavailability(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UserUnavailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	period, err := h.service.ScheduleUnavailability(r.Context(), authCtx.OrganizationID, authCtx.UserID, userID, req)
+	if err != nil {
+		writeTeamCapacityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(period)
+}
+
+func (h *TeamCapacityHandler) DeleteUnavailability(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid unavailability ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteUnavailability(r.Context(), authCtx.OrganizationID, userID, id); err != nil {
+		writeTeamCapacityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTeamCapacityError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidTeamCapacity):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrUserUnavailabilityNotFound), errors.Is(err, sql.ErrNoRows),
+		strings.HasSuffix(err.Error(), "not found or access denied"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
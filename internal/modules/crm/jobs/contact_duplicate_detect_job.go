@@ -8,7 +8,6 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
-	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 	"github.com/KevTiv/alieze-erp/pkg/queue"
 )
 
@@ -44,14 +43,8 @@ func (h *ContactDuplicateDetectJobHandler) Handle(ctx context.Context, job *queu
 		return fmt.Errorf("failed to unmarshal job payload: %w", err)
 	}
 
-	// Create request for duplicate detection
-	req := types.DetectDuplicatesRequest{
-		Threshold: &payload.Threshold,
-		Limit:     &payload.Limit,
-	}
-
 	// Execute duplicate detection
-	response, err := h.mergeService.DetectDuplicates(ctx, payload.OrganizationID, req)
+	response, err := h.mergeService.DetectDuplicates(ctx, payload.OrganizationID, &payload.Threshold, &payload.Limit)
 	if err != nil {
 		return fmt.Errorf("failed to detect duplicates: %w", err)
 	}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeContactScore = "contact.score.recalculate"
+
+// ContactScoreJobHandler handles queued passes of the contact score worker
+type ContactScoreJobHandler struct {
+	contactService *service.ContactServiceV2
+	logger         *slog.Logger
+}
+
+func NewContactScoreJobHandler(contactService *service.ContactServiceV2, logger *slog.Logger) *ContactScoreJobHandler {
+	return &ContactScoreJobHandler{
+		contactService: contactService,
+		logger:         logger,
+	}
+}
+
+// Handle recalculates the scores of all contacts as of the job's scheduled
+// time, recording them as that day's history
+func (h *ContactScoreJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	at := job.ScheduledAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	run, err := h.contactService.RecalculateContactScores(ctx, at.UTC())
+	if run != nil && run.Scored > 0 {
+		h.logger.Info("Contact scores recalculated",
+			"scored_on", run.ScoredOn.Format(time.DateOnly),
+			"scored", run.Scored)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to recalculate contact scores: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *ContactScoreJobHandler) JobType() string {
+	return JobTypeContactScore
+}
+
+// ContactScoreScheduler runs the contact score worker on a fixed interval
+type ContactScoreScheduler struct {
+	handler     *ContactScoreJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewContactScoreScheduler(handler *ContactScoreJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *ContactScoreScheduler {
+	return &ContactScoreScheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start recalculates contact scores every interval until ctx is cancelled
+func (s *ContactScoreScheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeContactScore, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeContactScore, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled contact score recalculation failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule contact score recalculation", "error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeLeadCadence = "lead.cadence.run"
+
+// LeadCadenceJobHandler handles queued passes of the lead cadence worker
+type LeadCadenceJobHandler struct {
+	leadService *service.LeadService
+	logger      *slog.Logger
+}
+
+func NewLeadCadenceJobHandler(leadService *service.LeadService, logger *slog.Logger) *LeadCadenceJobHandler {
+	return &LeadCadenceJobHandler{
+		leadService: leadService,
+		logger:      logger,
+	}
+}
+
+// Handle enrolls leads in cadences and completes or stops enrollments
+func (h *LeadCadenceJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.leadService.RunCadences(ctx, time.Now())
+	if run != nil && (run.Enrolled > 0 || run.Completed > 0 || run.Stopped > 0) {
+		h.logger.Info("Lead cadences run",
+			"enrolled", run.Enrolled,
+			"completed", run.Completed,
+			"stopped", run.Stopped)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run lead cadences: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *LeadCadenceJobHandler) JobType() string {
+	return JobTypeLeadCadence
+}
+
+// LeadCadenceScheduler runs the lead cadence worker on a fixed interval
+type LeadCadenceScheduler struct {
+	handler     *LeadCadenceJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewLeadCadenceScheduler(handler *LeadCadenceJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *LeadCadenceScheduler {
+	return &LeadCadenceScheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs lead cadences every interval until ctx is cancelled
+func (s *LeadCadenceScheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeLeadCadence, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeLeadCadence, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled lead cadence run failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule lead cadence runs", "error", err)
+	}
+}
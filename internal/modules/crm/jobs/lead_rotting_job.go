@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeLeadRotting = "lead.rotting.rotate"
+
+// LeadRottingJobHandler handles queued passes of the lead rotting worker
+type LeadRottingJobHandler struct {
+	leadService *service.LeadService
+	logger      *slog.Logger
+}
+
+func NewLeadRottingJobHandler(leadService *service.LeadService, logger *slog.Logger) *LeadRottingJobHandler {
+	return &LeadRottingJobHandler{
+		leadService: leadService,
+		logger:      logger,
+	}
+}
+
+// Handle rotates the leads left untouched past their stage's rotting days
+func (h *LeadRottingJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.leadService.RotateRottingLeads(ctx, time.Now())
+	if run != nil && (run.Rotted > 0 || run.Failed > 0) {
+		h.logger.Info("Rotting leads rotated",
+			"rotted", run.Rotted,
+			"reassigned", run.Reassigned,
+			"returned_to_pool", run.Pooled,
+			"kept", run.Kept,
+			"no_rule", run.NoRule,
+			"failed", run.Failed)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate rotting leads: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *LeadRottingJobHandler) JobType() string {
+	return JobTypeLeadRotting
+}
+
+// LeadRottingScheduler runs the lead rotting worker on a fixed interval
+type LeadRottingScheduler struct {
+	handler     *LeadRottingJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewLeadRottingScheduler(handler *LeadRottingJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *LeadRottingScheduler {
+	return &LeadRottingScheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start rotates rotting leads every interval until ctx is cancelled
+func (s *LeadRottingScheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeLeadRotting, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeLeadRotting, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled lead rotting failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule lead rotting", "error", err)
+	}
+}
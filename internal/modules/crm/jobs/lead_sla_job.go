@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeLeadSLA = "lead.sla.enforce"
+
+// LeadSLAJobHandler handles queued passes of the lead SLA worker
+type LeadSLAJobHandler struct {
+	leadService *service.LeadService
+	logger      *slog.Logger
+}
+
+func NewLeadSLAJobHandler(leadService *service.LeadService, logger *slog.Logger) *LeadSLAJobHandler {
+	return &LeadSLAJobHandler{
+		leadService: leadService,
+		logger:      logger,
+	}
+}
+
+// Handle starts and stops SLA clocks, flags breaches and escalates them
+func (h *LeadSLAJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.leadService.EnforceSLAs(ctx, time.Now())
+	if run != nil && (run.Breached > 0 || run.Escalated > 0) {
+		h.logger.Info("Lead SLAs enforced",
+			"started", run.Started,
+			"responded", run.Responded,
+			"breached", run.Breached,
+			"escalated", run.Escalated)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enforce lead SLAs: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *LeadSLAJobHandler) JobType() string {
+	return JobTypeLeadSLA
+}
+
+// LeadSLAScheduler runs the lead SLA worker on a fixed interval
+type LeadSLAScheduler struct {
+	handler     *LeadSLAJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewLeadSLAScheduler(handler *LeadSLAJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *LeadSLAScheduler {
+	return &LeadSLAScheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start enforces lead SLAs every interval until ctx is cancelled
+func (s *LeadSLAScheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeLeadSLA, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeLeadSLA, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled lead SLA enforcement failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule lead SLA enforcement", "error", err)
+	}
+}
@@ -2,14 +2,28 @@ package crm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/jobs"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/attachments"
 	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
 	"github.com/KevTiv/alieze-erp/pkg/crm/base"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/push"
 	"github.com/KevTiv/alieze-erp/pkg/registry"
 
 	"github.com/google/uuid"
@@ -18,14 +32,32 @@ import (
 
 // CRMModule represents the CRM module
 type CRMModule struct {
-	contactHandler        *handler.ContactHandler
+	contactHandler        *handler.ContactHandlerV2
 	salesTeamHandler      *handler.SalesTeamHandler
 	activityHandler       *handler.ActivityHandler
 	leadStageHandler      *handler.LeadStageHandler
 	leadSourceHandler     *handler.LeadSourceHandler
 	lostReasonHandler     *handler.LostReasonHandler
 	leadHandler           *handler.LeadHandler
+	leadCaptureHandler    *handler.LeadCaptureHandler
+	leadEmailHandler      *handler.LeadEmailHandler
+	schedulingLinkHandler *handler.SchedulingLinkHandler
+	leadShareHandler      *handler.LeadShareHandler
+	syncHandler           *handler.SyncHandler
+	contactConsentHandler *handler.ContactConsentHandler
+	contactConsentService *service.ContactConsentService
+	contactService        *service.ContactServiceV2
+	leadService           *service.LeadService
+	assignmentRuleService *service.AssignmentRuleService
 	assignmentRuleHandler *handler.AssignmentRuleHandler
+	teamCapacityHandler   *handler.TeamCapacityHandler
+	crmTagHandler         *handler.CRMTagHandler
+	attachmentService     *attachments.Service
+	slaScheduler          *jobs.LeadSLAScheduler
+	cadenceScheduler      *jobs.LeadCadenceScheduler
+	rottingScheduler      *jobs.LeadRottingScheduler
+	snapshotScheduler     *jobs.LeadPipelineSnapshotScheduler
+	contactScoreScheduler *jobs.ContactScoreScheduler
 	logger                *slog.Logger
 }
 
@@ -45,15 +77,42 @@ func (m *CRMModule) Init(ctx context.Context, deps registry.Dependencies) error
 	m.logger = deps.Logger.With("module", "crm")
 	m.logger.Info("Initializing CRM module")
 
-	// Create repositories
+	// Create repositories; lead listing and search render SQL for the
+	// configured database dialect
+	dialect := database.DialectOrDefault(deps.Dialect)
 	contactRepo := repository.NewContactRepository(deps.DB)
+	contactSearchRepo := repository.NewContactSearchRepository(deps.DB, dialect)
+	contactMergeRepo := repository.NewContactMergeRepository(deps.DB)
+	contactScoreRepo := repository.NewContactScoreRepository(deps.DB)
+	contactGDPRRepo := repository.NewContactGDPRRepository(deps.DB)
+	contactConsentRepo := repository.NewContactConsentRepository(deps.DB)
 	salesTeamRepo := repository.NewSalesTeamRepository(deps.DB)
 	activityRepo := repository.NewActivityRepository(deps.DB)
 	leadStageRepo := repository.NewLeadStageRepository(deps.DB)
+	leadStageHistoryRepo := repository.NewLeadStageHistoryRepository(deps.DB)
+	leadConversionRepo := repository.NewLeadConversionRepository(deps.DB)
+	leadOutcomeRepo := repository.NewLeadOutcomeRepository(deps.DB)
+	leadSearchRepo := repository.NewLeadSearchRepository(deps.DB, dialect)
+	leadBoardRepo := repository.NewLeadBoardRepository(deps.DB, dialect)
+	leadSLARepo := repository.NewLeadSLARepository(deps.DB)
+	leadCadenceRepo := repository.NewLeadCadenceRepository(deps.DB)
+	leadBulkRepo := repository.NewLeadBulkRepository(deps.DB)
+	leadAuditRepo := repository.NewLeadAuditRepository(deps.DB)
+	leadRottingRepo := repository.NewLeadRottingRepository(deps.DB)
+	leadReassignRepo := repository.NewLeadReassignRepository(deps.DB)
+	leadPipelineSnapshotRepo := repository.NewLeadPipelineSnapshotRepository(deps.DB)
+	leadTrashRepo := repository.NewLeadTrashRepository(deps.DB)
 	leadSourceRepo := repository.NewLeadSourceRepository(deps.DB)
 	lostReasonRepo := repository.NewLostReasonRepository(deps.DB)
-	leadRepo := repository.NewLeadRepository(deps.DB)
+	leadRepo := repository.NewLeadRepository(deps.DB, dialect)
+	leadCaptureFormRepo := repository.NewLeadCaptureFormRepository(deps.DB)
+	leadEmailRepo := repository.NewLeadEmailRepository(deps.DB)
+	schedulingLinkRepo := repository.NewSchedulingLinkRepository(deps.DB)
+	leadShareRepo := repository.NewLeadShareRepository(deps.DB)
+	syncRepo := repository.NewSyncRepository(deps.DB)
 	assignmentRuleRepo := repository.NewAssignmentRuleRepository(deps.DB)
+	teamCapacityRepo := repository.NewTeamCapacityRepository(deps.DB)
+	crmTagRepo := repository.NewCRMTagRepository(deps.DB)
 
 	// Create services - using shared auth adapter with rule engine integration
 	// The adapter implements both legacy and base auth service interfaces
@@ -65,28 +124,178 @@ func (m *CRMModule) Init(ctx context.Context, deps registry.Dependencies) error
 		RuleEngine: deps.RuleEngine,
 		EventBus:   deps.EventBus,
 	})
+	contactService.SetScoring(contactScoreRepo)
+	customFieldStore := customfields.NewStore(deps.DB)
+	contactService.SetSearch(contactSearchRepo, customFieldStore)
+	// Dashboards are cached in memory until a shared store is set
+	contactService.SetDashboardCache(cache.NewOrgCache(cache.NewLRU(0), dashboardCacheNamespace, service.DashboardCacheTTL, m.logger))
+	m.contactService = contactService
+	contactMergeService := service.NewContactMergeService(contactMergeRepo, contactRepo, authAdapter, deps.EventBus)
+	contactGDPRService := service.NewContactGDPRService(contactGDPRRepo, authAdapter, deps.EventBus)
+	m.contactConsentService = service.NewContactConsentService(contactConsentRepo, contactRepo, authAdapter, deps.EventBus)
 	salesTeamService := service.NewSalesTeamService(salesTeamRepo, authAdapter, deps.EventBus)
 	activityService := service.NewActivityService(activityRepo, authAdapter, deps.EventBus)
 	leadStageService := service.NewLeadStageService(leadStageRepo, authAdapter, deps.EventBus)
 	leadSourceService := service.NewLeadSourceService(leadSourceRepo, authAdapter, deps.EventBus)
 	lostReasonService := service.NewLostReasonService(lostReasonRepo, authAdapter, deps.EventBus)
 	assignmentRuleService := service.NewAssignmentRuleService(assignmentRuleRepo, authAdapter, deps.EventBus)
+	businessCalendars := calendar.NewStore(deps.DB)
+	assignmentRuleService.SetBusinessCalendars(businessCalendars)
+	assignmentRuleService.Engine().SetWorkingHours(businessCalendars)
 	leadService := service.NewLeadService(leadRepo, authAdapter, deps.EventBus, assignmentRuleService)
+	leadService.SetComputedFields(computed.NewStore(deps.DB))
+	leadService.SetCustomFields(customFieldStore)
+	leadService.SetStageHistory(leadStageHistoryRepo, leadStageRepo)
+	leadService.SetConversion(leadConversionRepo)
+	leadService.SetOutcomes(leadOutcomeRepo)
+	leadService.SetSearch(leadSearchRepo)
+	leadService.SetBoard(leadBoardRepo, leadStageRepo)
+	leadService.SetSLA(leadSLARepo, businessCalendars)
+	leadService.SetBulk(leadBulkRepo)
+	leadService.SetCadences(leadCadenceRepo, leadSourceRepo)
+	leadService.SetAudit(leadAuditRepo)
+	leadService.SetRotting(leadRottingRepo)
+	leadService.SetReassignment(leadReassignRepo, assignmentRuleRepo, salesTeamRepo)
+	leadService.SetPipelineSnapshots(leadPipelineSnapshotRepo)
+	leadService.SetFiscalPeriods(fiscal.NewPeriods(fiscal.NewStore(deps.DB)))
+	leadService.SetTrash(leadTrashRepo)
+	m.attachmentService = attachments.NewService(attachments.NewStore(deps.DB), attachments.DefaultPolicy())
+	leadService.SetAttachments(m.attachmentService)
+	m.leadService = leadService
+	m.assignmentRuleService = assignmentRuleService
+	leadCaptureService := service.NewLeadCaptureService(leadCaptureFormRepo, leadRepo, leadService, authAdapter, deps.EventBus)
+	leadEmailService := service.NewLeadEmailService(leadEmailRepo, leadRepo, leadService, authAdapter, deps.EventBus)
+	schedulingLinkService := service.NewSchedulingLinkService(schedulingLinkRepo, leadRepo, salesTeamRepo, leadSourceRepo, leadService, authAdapter, deps.EventBus)
+	schedulingLinkService.SetCalendars(businessCalendars, businessCalendars)
+	leadShareService := service.NewLeadShareService(leadShareRepo, leadService, authAdapter, deps.EventBus)
+	syncService := service.NewSyncService(syncRepo, leadService, contactService, activityService, authAdapter)
+	teamCapacityService := service.NewTeamCapacityService(teamCapacityRepo, salesTeamRepo, authAdapter)
+	crmTagService := service.NewCRMTagService(crmTagRepo, authAdapter)
 
 	// Create handlers
-	m.contactHandler = handler.NewContactHandler(contactService)
+	m.contactHandler = handler.NewContactHandlerV2(contactService, contactMergeService, contactGDPRService)
+	m.contactHandler.SetAPIVersions(deps.APIVersions)
 	m.salesTeamHandler = handler.NewSalesTeamHandler(salesTeamService)
 	m.activityHandler = handler.NewActivityHandler(activityService)
 	m.leadStageHandler = handler.NewLeadStageHandler(leadStageService)
 	m.leadSourceHandler = handler.NewLeadSourceHandler(leadSourceService)
 	m.lostReasonHandler = handler.NewLostReasonHandler(lostReasonService)
 	m.leadHandler = handler.NewLeadHandler(leadService)
+	m.leadCaptureHandler = handler.NewLeadCaptureHandler(leadCaptureService)
+	m.leadEmailHandler = handler.NewLeadEmailHandler(leadEmailService)
+	m.schedulingLinkHandler = handler.NewSchedulingLinkHandler(schedulingLinkService)
+	m.leadShareHandler = handler.NewLeadShareHandler(leadShareService)
+	m.syncHandler = handler.NewSyncHandler(syncService)
+	m.contactConsentHandler = handler.NewContactConsentHandler(m.contactConsentService)
 	m.assignmentRuleHandler = handler.NewAssignmentRuleHandler(assignmentRuleService, authAdapter)
+	m.teamCapacityHandler = handler.NewTeamCapacityHandler(teamCapacityService)
+	m.crmTagHandler = handler.NewCRMTagHandler(crmTagService)
+
+	// Start the lead SLA worker
+	slaJobHandler := jobs.NewLeadSLAJobHandler(leadService, m.logger)
+	m.slaScheduler = jobs.NewLeadSLAScheduler(slaJobHandler, service.LeadSLAInterval, deps.Scheduler, m.logger)
+	m.slaScheduler.Start(ctx)
+
+	// Start the lead cadence worker
+	cadenceJobHandler := jobs.NewLeadCadenceJobHandler(leadService, m.logger)
+	m.cadenceScheduler = jobs.NewLeadCadenceScheduler(cadenceJobHandler, service.LeadCadenceInterval, deps.Scheduler, m.logger)
+	m.cadenceScheduler.Start(ctx)
+
+	// Start the lead rotting worker
+	rottingJobHandler := jobs.NewLeadRottingJobHandler(leadService, m.logger)
+	m.rottingScheduler = jobs.NewLeadRottingScheduler(rottingJobHandler, service.LeadRottingInterval, deps.Scheduler, m.logger)
+	m.rottingScheduler.Start(ctx)
+
+	// Start the weekly pipeline snapshot
+	snapshotJobHandler := jobs.NewLeadPipelineSnapshotJobHandler(leadService, m.logger)
+	m.snapshotScheduler = jobs.NewLeadPipelineSnapshotScheduler(snapshotJobHandler, service.LeadPipelineSnapshotInterval, deps.Scheduler, m.logger)
+	m.snapshotScheduler.Start(ctx)
+
+	// Start the nightly contact score worker
+	contactScoreJobHandler := jobs.NewContactScoreJobHandler(contactService, m.logger)
+	m.contactScoreScheduler = jobs.NewContactScoreScheduler(contactScoreJobHandler, service.ContactScoreInterval, deps.Scheduler, m.logger)
+	m.contactScoreScheduler.Start(ctx)
 
 	m.logger.Info("CRM module initialized successfully")
 	return nil
 }
 
+// SetLegacyLeadFilterRoutes sets whether the deprecated
+// /api/v1/leads/by-<filter> routes are served. It must be called after
+// Init and before RegisterRoutes.
+func (m *CRMModule) SetLegacyLeadFilterRoutes(enabled bool) {
+	if m.leadHandler != nil {
+		m.leadHandler.SetLegacyFilterRoutes(enabled)
+	}
+}
+
+// SetDashboardStore keeps the CRM and activity dashboards in store, such as
+// Redis shared by every instance, instead of each instance's memory. It must
+// be called after Init.
+func (m *CRMModule) SetDashboardStore(store cache.Store) {
+	if m.contactService != nil {
+		m.contactService.SetDashboardCache(cache.NewOrgCache(store, dashboardCacheNamespace, service.DashboardCacheTTL, m.logger))
+	}
+}
+
+// SetLeadListTotals sets whether lead list pages count all matching leads.
+// It must be called after Init.
+func (m *CRMModule) SetLeadListTotals(enabled bool) {
+	if m.leadService != nil {
+		m.leadService.SetListTotals(enabled)
+	}
+}
+
+// SetMailer emails contacts the links confirming their email opt-ins. It
+// must be called after Init.
+func (m *CRMModule) SetMailer(mailer service.ConsentMailer) {
+	if m.contactConsentService != nil {
+		m.contactConsentService.SetMailer(mailer)
+	}
+}
+
+// SetPublicURL sets the API's public address the links emailed to contacts
+// open. It must be called after Init.
+func (m *CRMModule) SetPublicURL(url string) {
+	if m.contactConsentService != nil {
+		m.contactConsentService.SetPublicURL(url)
+	}
+}
+
+// SetPushNotifier tells team leaders which of their team's leads rotted and
+// were rotated. It must be called after Init.
+func (m *CRMModule) SetPushNotifier(notifier push.Notifier) {
+	if m.leadService != nil {
+		m.leadService.SetPushNotifier(notifier)
+	}
+}
+
+// SetStorage sets the file storage lead attachments are kept in, and the
+// provider name recorded with them. Uploads fail until it is set. It must be
+// called after Init.
+func (m *CRMModule) SetStorage(files attachments.FileStore, provider string) {
+	if m.attachmentService != nil {
+		m.attachmentService.SetStorage(files, provider)
+	}
+}
+
+// SetEntitlements makes lead creation and active assignment rules count
+// against the organization's plan. It must be called after Init.
+func (m *CRMModule) SetEntitlements(checker entitlements.Checker) {
+	if m.leadService != nil {
+		m.leadService.SetEntitlements(checker)
+	}
+	if m.assignmentRuleService != nil {
+		m.assignmentRuleService.SetEntitlements(checker)
+	}
+}
+
+// ContactConsentService tells the modules that reach contacts whether the
+// contact allows it. It is nil before Init.
+func (m *CRMModule) ContactConsentService() *service.ContactConsentService {
+	return m.contactConsentService
+}
+
 // RegisterRoutes registers CRM module routes
 func (m *CRMModule) RegisterRoutes(router interface{}) {
 	if router == nil {
@@ -115,9 +324,33 @@ func (m *CRMModule) RegisterRoutes(router interface{}) {
 		if m.leadHandler != nil {
 			m.leadHandler.RegisterRoutes(r)
 		}
+		if m.leadCaptureHandler != nil {
+			m.leadCaptureHandler.RegisterRoutes(r)
+		}
+		if m.leadEmailHandler != nil {
+			m.leadEmailHandler.RegisterRoutes(r)
+		}
+		if m.schedulingLinkHandler != nil {
+			m.schedulingLinkHandler.RegisterRoutes(r)
+		}
+		if m.leadShareHandler != nil {
+			m.leadShareHandler.RegisterRoutes(r)
+		}
+		if m.syncHandler != nil {
+			m.syncHandler.RegisterRoutes(r)
+		}
+		if m.contactConsentHandler != nil {
+			m.contactConsentHandler.RegisterRoutes(r)
+		}
 		if m.assignmentRuleHandler != nil {
 			m.assignmentRuleHandler.RegisterRoutes(r)
 		}
+		if m.teamCapacityHandler != nil {
+			m.teamCapacityHandler.RegisterRoutes(r)
+		}
+		if m.crmTagHandler != nil {
+			m.crmTagHandler.RegisterRoutes(r)
+		}
 	}
 }
 
@@ -140,6 +373,49 @@ func (m *CRMModule) RegisterEventHandlers(bus interface{}) {
 
 		m.logger.Info("CRM module event handlers registered")
 	}
+
+	if eventBus, ok := bus.(*events.Bus); ok && m.contactService != nil {
+		for _, eventType := range dashboardEvents {
+			eventBus.Subscribe(eventType, m.handleDashboardChange)
+		}
+	}
+}
+
+// dashboardCacheNamespace scopes the cached dashboards' keys in the store
+const dashboardCacheNamespace = "crm:dashboards"
+
+// dashboardEvents change the contacts and activities the CRM and activity
+// dashboards are computed from
+var dashboardEvents = []string{
+	"contact.created",
+	"contact.updated",
+	"contact.deleted",
+	"contact.merged",
+	"contact.anonymized",
+	"crm.activity.created",
+	"crm.activity.updated",
+	"crm.activity.completed",
+	"crm.activity.deleted",
+	"import.file_imported",
+}
+
+// handleDashboardChange drops the cached dashboards of the organization the
+// event changed. The payload might be the record itself or a map, depending
+// on how it was published.
+func (m *CRMModule) handleDashboardChange(ctx context.Context, event events.Event) error {
+	var payload struct {
+		OrganizationID uuid.UUID `json:"organization_id"`
+	}
+	bytes, err := json.Marshal(event.Payload)
+	if err == nil {
+		err = json.Unmarshal(bytes, &payload)
+	}
+	if err != nil || payload.OrganizationID == uuid.Nil {
+		m.logger.Warn("Failed to read organization of dashboard change event", "event", event.Type, "error", err)
+		return nil
+	}
+	m.contactService.InvalidateDashboards(ctx, payload.OrganizationID)
+	return nil
 }
 
 // handleOrderCreated handles order creation events
@@ -327,6 +603,11 @@ func (m *CRMModule) handleInvoiceCreated(ctx context.Context, event interface{})
 	return nil
 }
 
+// Enums returns the enumerations the CRM module lists to clients
+func (m *CRMModule) Enums() []enums.Enum {
+	return []enums.Enum{types.LeadTypeEnum, types.LeadPriorityEnum, types.LeadWonStatusEnum, types.LeadStatusEnum}
+}
+
 // Health checks the health of the CRM module
 func (m *CRMModule) Health() error {
 	return nil
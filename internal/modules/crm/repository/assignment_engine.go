@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// FindMatchingRule finds the organization's rule to apply to an assignment
+func (r *AssignmentRuleRepositoryPostgres) FindMatchingRule(ctx context.Context, orgID uuid.UUID, targetModel string, conditions map[string]interface{}) (*types.AssignmentRule, error) {
+	conditionsJSON, err := json.Marshal(conditions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conditions: %w", err)
+	}
+
+	// Assignment windows and active days (ISO, 1=Monday) are evaluated in the
+	// time zone of the organization's default business calendar. A window whose
+	// start is after its end spans midnight. Rules with a window or active days
+	// do not apply on the holidays of that calendar.
+	query := `
+		SELECT r.id, r.organization_id, r.name, r.rule_type, r.target_model, r.priority,
+		       r.assignment_config, COALESCE(r.max_assignments_per_user, 0)
+		FROM assignment_rules r
+		CROSS JOIN LATERAL (
+			SELECT now() AT TIME ZONE COALESCE(bc.timezone, 'UTC') AS local_now, bc.holiday_set_id
+			FROM (SELECT 1) one
+			LEFT JOIN business_calendars bc
+				ON bc.organization_id = r.organization_id
+				AND bc.team_id IS NULL AND bc.is_default = true AND bc.active = true
+		) tz
+		WHERE r.organization_id = $1
+		AND r.target_model = $2
+		AND r.is_active = true
+		AND r.conditions @> $3
+		AND (r.active_days IS NULL OR cardinality(r.active_days) = 0
+			OR EXTRACT(ISODOW FROM tz.local_now)::int = ANY(r.active_days))
+		AND (r.assignment_window_start IS NULL OR r.assignment_window_end IS NULL
+			OR (r.assignment_window_start <= r.assignment_window_end
+				AND tz.local_now::time >= r.assignment_window_start AND tz.local_now::time < r.assignment_window_end)
+			OR (r.assignment_window_start > r.assignment_window_end
+				AND (tz.local_now::time >= r.assignment_window_start OR tz.local_now::time < r.assignment_window_end)))
+		AND (((r.assignment_window_start IS NULL OR r.assignment_window_end IS NULL)
+				AND (r.active_days IS NULL OR cardinality(r.active_days) = 0))
+			OR NOT EXISTS (
+				SELECT 1 FROM business_holidays h
+				WHERE h.holiday_set_id = tz.holiday_set_id AND h.holiday_date = tz.local_now::date
+			))
+		ORDER BY r.priority DESC
+		LIMIT 1
+	`
+
+	var rule types.AssignmentRule
+	err = r.db.QueryRowContext(ctx, query, orgID, targetModel, conditionsJSON).Scan(
+		&rule.ID, &rule.OrganizationID, &rule.Name, &rule.RuleType, &rule.TargetModel, &rule.Priority,
+		&rule.AssignmentConfig, &rule.MaxAssignmentsPerUser)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrNoMatchingAssignmentRule
+		}
+		return nil, fmt.Errorf("failed to find matching rule: %w", err)
+	}
+	rule.IsActive = true
+	return &rule, nil
+}
+
+// ListCandidateLoads returns the users' loads. Users without a load row are
+// idle, available and of weight 1; a user is unavailable until their
+// unavailable_until has passed and while one of their scheduled
+// unavailability periods is in progress.
+func (r *AssignmentRuleRepositoryPostgres) ListCandidateLoads(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id,
+		       COALESCE(l.active_assignments, 0), COALESCE(l.total_assignments, 0), l.last_assigned_at,
+		       COALESCE(l.max_capacity, 0), COALESCE(l.weight, 1),
+		       COALESCE(l.is_available, true) AND (l.unavailable_until IS NULL OR l.unavailable_until <= now())
+		           AND NOT EXISTS (
+		               SELECT 1 FROM user_unavailability p
+		               WHERE p.organization_id = $2 AND p.user_id = u.id AND p.starts_at <= now() AND p.ends_at > now()
+		           ),
+		       l.unavailable_until
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS u(id, position)
+		LEFT JOIN user_assignment_load l
+			ON l.user_id = u.id AND l.organization_id = $2 AND l.target_model = $3
+		ORDER BY u.position
+	`, pq.Array(userIDs), orgID, targetModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user assignment loads: %w", err)
+	}
+	defer rows.Close()
+
+	loads := make([]types.UserAssignmentLoad, 0, len(userIDs))
+	for rows.Next() {
+		load := types.UserAssignmentLoad{OrganizationID: orgID, TargetModel: targetModel}
+		var lastAssignedAt, unavailableUntil sql.NullTime
+		if err := rows.Scan(&load.UserID, &load.ActiveAssignments, &load.TotalAssignments, &lastAssignedAt,
+			&load.MaxCapacity, &load.Weight, &load.IsAvailable, &unavailableUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan user assignment load: %w", err)
+		}
+		load.LastAssignedAt = lastAssignedAt.Time
+		load.UnavailableUntil = unavailableUntil.Time
+		loads = append(loads, load)
+	}
+	return loads, rows.Err()
+}
+
+// GetUserName returns the name of an assignee
+func (r *AssignmentRuleRepositoryPostgres) GetUserName(ctx context.Context, userID uuid.UUID) (string, error) {
+	var name string
+	if err := r.db.QueryRowContext(ctx, `SELECT name FROM users WHERE id = $1`, userID).Scan(&name); err != nil {
+		return "", fmt.Errorf("failed to get user name: %w", err)
+	}
+	return name, nil
+}
+
+// RecordAssignment records an assignment chosen by a rule. A record without
+// an assignee is returned to the pool: it is unassigned and recorded in the
+// history as assigned to the pool.
+func (r *AssignmentRuleRepositoryPostgres) RecordAssignment(ctx context.Context, record types.AssignmentRecord) error {
+	var assignedToID *uuid.UUID
+	assignedToType := "pool"
+	if record.AssignedToID != uuid.Nil {
+		assignedToID = &record.AssignedToID
+		assignedToType = "user"
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The round robin position only moves from where it was read
+	if record.Cursor != nil {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE assignment_rules
+			SET assignment_config = jsonb_set(assignment_config, '{current_index}', to_jsonb($2::int)),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND COALESCE((assignment_config->>'current_index')::int, 0) = $3
+		`, record.RuleID, record.Cursor.To, record.Cursor.From)
+		if err != nil {
+			return fmt.Errorf("failed to move round robin position: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to move round robin position: %w", err)
+		} else if n == 0 {
+			return types.ErrAssignmentConflict
+		}
+	}
+
+	if record.TargetModel == string(types.AssignmentTargetModelLeads) {
+		_, err = tx.ExecContext(ctx, `UPDATE leads SET assigned_to = $1, updated_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = $2 AND organization_id = $3`, assignedToID, record.TargetID, record.OrganizationID)
+		if err != nil {
+			return fmt.Errorf("failed to update lead assignment: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO assignment_history (
+			organization_id, rule_id, rule_name, target_model, target_id,
+			assigned_to_type, assigned_to_id, previous_assigned_to_id, assignment_reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, record.OrganizationID, record.RuleID, record.RuleName, record.TargetModel, record.TargetID,
+		assignedToType, assignedToID, record.PreviousAssignedToID, record.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to create assignment history: %w", err)
+	}
+
+	// Records returned to the pool add to no one's load
+	if assignedToID != nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_assignment_load (
+				organization_id, user_id, target_model, active_assignments, total_assignments, last_assigned_at
+			) VALUES ($1, $2, $3, 1, 1, CURRENT_TIMESTAMP)
+			ON CONFLICT (organization_id, user_id, target_model)
+			DO UPDATE SET
+				active_assignments = user_assignment_load.active_assignments + 1,
+				total_assignments = user_assignment_load.total_assignments + 1,
+				last_assigned_at = CURRENT_TIMESTAMP,
+				updated_at = CURRENT_TIMESTAMP
+		`, record.OrganizationID, record.AssignedToID, record.TargetModel)
+		if err != nil {
+			return fmt.Errorf("failed to update user assignment load: %w", err)
+		}
+	}
+
+	if record.PreviousAssignedToID != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_assignment_load
+			SET active_assignments = GREATEST(active_assignments - 1, 0),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE organization_id = $1 AND user_id = $2 AND target_model = $3
+		`, record.OrganizationID, *record.PreviousAssignedToID, record.TargetModel)
+		if err != nil {
+			return fmt.Errorf("failed to release previous assignee load: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // AssignmentRuleRepositoryPostgres implements AssignmentRuleRepository for PostgreSQL
@@ -1089,8 +1090,8 @@ func (r *AssignmentRuleRepositoryPostgres) CreateTerritory(ctx context.Context,
 		territory.Description,
 		territory.TerritoryType,
 		conditionsJSON,
-		territory.AssignedUsers,
-		territory.AssignedTeams,
+		pq.Array(territory.AssignedUsers),
+		pq.Array(territory.AssignedTeams),
 		territory.Priority,
 		territory.IsActive,
 		territory.CreatedBy,
@@ -1124,8 +1125,8 @@ func (r *AssignmentRuleRepositoryPostgres) GetTerritory(ctx context.Context, id
 		&territory.Description,
 		&territory.TerritoryType,
 		&conditionsJSON,
-		&territory.AssignedUsers,
-		&territory.AssignedTeams,
+		pq.Array(&territory.AssignedUsers),
+		pq.Array(&territory.AssignedTeams),
 		&territory.Priority,
 		&territory.IsActive,
 		&territory.CreatedAt,
@@ -1177,8 +1178,8 @@ func (r *AssignmentRuleRepositoryPostgres) UpdateTerritory(ctx context.Context,
 		territory.Description,
 		territory.TerritoryType,
 		conditionsJSON,
-		territory.AssignedUsers,
-		territory.AssignedTeams,
+		pq.Array(territory.AssignedUsers),
+		pq.Array(territory.AssignedTeams),
 		territory.Priority,
 		territory.IsActive,
 		territory.UpdatedBy,
@@ -1236,8 +1237,8 @@ func (r *AssignmentRuleRepositoryPostgres) ListTerritories(ctx context.Context,
 			&territory.Description,
 			&territory.TerritoryType,
 			&conditionsJSON,
-			&territory.AssignedUsers,
-			&territory.AssignedTeams,
+			pq.Array(&territory.AssignedUsers),
+			pq.Array(&territory.AssignedTeams),
 			&territory.Priority,
 			&territory.IsActive,
 			&territory.CreatedAt,
@@ -1260,131 +1261,6 @@ func (r *AssignmentRuleRepositoryPostgres) ListTerritories(ctx context.Context,
 	return territories, nil
 }
 
-// AssignLead assigns a lead to a user
-func (r *AssignmentRuleRepositoryPostgres) AssignLead(ctx context.Context, leadID uuid.UUID, userID uuid.UUID, reason string) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Update lead assignment
-	leadQuery := `UPDATE leads SET assigned_to = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	_, err = tx.ExecContext(ctx, leadQuery, userID, leadID)
-	if err != nil {
-		return fmt.Errorf("failed to update lead assignment: %w", err)
-	}
-
-	// Record assignment history
-	history := &types.AssignmentHistory{
-		ID:               uuid.New(),
-		TargetModel:      "leads",
-		TargetID:         leadID,
-		AssignedToType:   "user",
-		AssignedToID:     userID,
-		AssignmentReason: reason,
-	}
-
-	err = r.CreateAssignmentHistory(ctx, history)
-	if err != nil {
-		return fmt.Errorf("failed to create assignment history: %w", err)
-	}
-
-	return tx.Commit()
-}
-
-// GetNextAssignee determines the next assignee based on assignment rules
-func (r *AssignmentRuleRepositoryPostgres) GetNextAssignee(ctx context.Context, targetModel string, conditions map[string]interface{}) (uuid.UUID, string, error) {
-	// Convert conditions to JSON for SQL query
-	conditionsJSON, err := json.Marshal(conditions)
-	if err != nil {
-		return uuid.Nil, "", fmt.Errorf("failed to marshal conditions: %w", err)
-	}
-
-	// Find matching assignment rule
-	var ruleID uuid.UUID
-	var ruleType string
-	var ruleConfig json.RawMessage
-
-	query := `
-		SELECT id, rule_type, assignment_config
-		FROM assignment_rules
-		WHERE target_model = $1
-		AND is_active = true
-		AND conditions @> $2
-		ORDER BY priority DESC
-		LIMIT 1
-	`
-
-	err = r.db.QueryRowContext(ctx, query, targetModel, conditionsJSON).Scan(&ruleID, &ruleType, &ruleConfig)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return uuid.Nil, "", fmt.Errorf("no matching assignment rule found")
-		}
-		return uuid.Nil, "", fmt.Errorf("failed to find matching rule: %w", err)
-	}
-
-	// Determine assignee based on rule type
-	var assigneeID uuid.UUID
-	var assigneeName string
-
-	switch ruleType {
-	case "round_robin":
-		// Call PostgreSQL function for round-robin assignment
-		err = r.db.QueryRowContext(ctx, "SELECT get_next_round_robin_user($1)", ruleID).Scan(&assigneeID)
-		if err != nil {
-			return uuid.Nil, "", fmt.Errorf("failed to get round-robin assignee: %w", err)
-		}
-
-	case "weighted":
-		// Call PostgreSQL function for weighted assignment
-		err = r.db.QueryRowContext(ctx, "SELECT get_weighted_user($1, $2)", ruleID, targetModel).Scan(&assigneeID)
-		if err != nil {
-			return uuid.Nil, "", fmt.Errorf("failed to get weighted assignee: %w", err)
-		}
-
-	case "territory":
-		// Match territory and get assigned users
-		var territoryID uuid.UUID
-		err = r.db.QueryRowContext(ctx, "SELECT match_territory($1, $2)", conditions["organization_id"], conditionsJSON).Scan(&territoryID)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return uuid.Nil, "", fmt.Errorf("failed to match territory: %w", err)
-		}
-
-		if territoryID != uuid.Nil {
-			// Get users assigned to this territory
-			var assignedUsers []uuid.UUID
-			query := `SELECT assigned_users FROM territories WHERE id = $1`
-			err = r.db.QueryRowContext(ctx, query, territoryID).Scan(&assignedUsers)
-			if err != nil {
-				return uuid.Nil, "", fmt.Errorf("failed to get territory users: %w", err)
-			}
-
-			if len(assignedUsers) > 0 {
-				assigneeID = assignedUsers[0] // Simple selection - could be enhanced
-			}
-		}
-
-	case "custom":
-		// For custom rules, we'd need additional logic
-		// This is a placeholder for custom implementation
-		return uuid.Nil, "", fmt.Errorf("custom assignment rules not yet implemented")
-	}
-
-	// Get assignee name
-	if assigneeID != uuid.Nil {
-		var name string
-		query := `SELECT name FROM users WHERE id = $1`
-		err = r.db.QueryRowContext(ctx, query, assigneeID).Scan(&name)
-		if err != nil {
-			return assigneeID, "", fmt.Errorf("failed to get assignee name: %w", err)
-		}
-		assigneeName = name
-	}
-
-	return assigneeID, assigneeName, nil
-}
-
 // GetAssignmentStatsByUser retrieves assignment statistics by user
 func (r *AssignmentRuleRepositoryPostgres) GetAssignmentStatsByUser(ctx context.Context, orgID uuid.UUID, targetModel string) ([]*types.AssignmentStatsByUser, error) {
 	query := `
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestListCandidateLoadsDefaultsUsersWithoutLoad(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, busy, idle := uuid.New(), uuid.New(), uuid.New()
+	lastAssigned := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`FROM unnest\(\$1::uuid\[\]\) WITH ORDINALITY AS u\(id, position\)`).
+		WithArgs(sqlmock.AnyArg(), orgID, "leads").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active", "total", "last_assigned_at", "capacity", "weight", "available", "unavailable_until"}).
+			AddRow(busy, 4, 20, lastAssigned, 5, 2, false, nil).
+			AddRow(idle, 0, 0, nil, 0, 1, true, nil))
+
+	repo := NewAssignmentRuleRepository(db)
+	loads, err := repo.ListCandidateLoads(context.Background(), orgID, "leads", []uuid.UUID{busy, idle})
+	require.NoError(t, err)
+	require.Len(t, loads, 2)
+	assert.Equal(t, types.UserAssignmentLoad{OrganizationID: orgID, UserID: busy, TargetModel: "leads", ActiveAssignments: 4,
+		TotalAssignments: 20, LastAssignedAt: lastAssigned, MaxCapacity: 5, Weight: 2}, loads[0])
+	assert.True(t, loads[1].IsAvailable)
+	assert.True(t, loads[1].LastAssignedAt.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordAssignmentMovesLoadInOneTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, ruleID, leadID, from, to := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	record := types.AssignmentRecord{
+		OrganizationID: orgID, RuleID: ruleID, RuleName: "Inbound", TargetModel: "leads", TargetID: leadID,
+		AssignedToID: to, PreviousAssignedToID: &from, Reason: "reassignment",
+		Cursor: &types.AssignmentCursor{From: 2, To: 0},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE assignment_rules\s+SET assignment_config = jsonb_set`).
+		WithArgs(ruleID, 0, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE leads SET assigned_to = \$1`).
+		WithArgs(to, leadID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO assignment_history`).
+		WithArgs(orgID, ruleID, "Inbound", "leads", leadID, "user", to, &from, "reassignment").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_assignment_load`).
+		WithArgs(orgID, to, "leads").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SET active_assignments = GREATEST\(active_assignments - 1, 0\)`).
+		WithArgs(orgID, from, "leads").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// A round robin position moved by another assignment rolls back
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE assignment_rules`).
+		WithArgs(ruleID, 0, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	repo := NewAssignmentRuleRepository(db)
+	require.NoError(t, repo.RecordAssignment(context.Background(), record))
+	assert.ErrorIs(t, repo.RecordAssignment(context.Background(), record), types.ErrAssignmentConflict)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordAssignmentReturnsLeadToPool(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, ruleID, leadID, from := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	record := types.AssignmentRecord{
+		OrganizationID: orgID, RuleID: ruleID, RuleName: "Rotting", TargetModel: "leads", TargetID: leadID,
+		PreviousAssignedToID: &from, Reason: "returned_to_pool",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE leads SET assigned_to = \$1`).
+		WithArgs(nil, leadID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO assignment_history`).
+		WithArgs(orgID, ruleID, "Rotting", "leads", leadID, "pool", nil, &from, "returned_to_pool").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The pool takes on no load, the previous assignee sheds theirs
+	mock.ExpectExec(`SET active_assignments = GREATEST\(active_assignments - 1, 0\)`).
+		WithArgs(orgID, from, "leads").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewAssignmentRuleRepository(db)
+	require.NoError(t, repo.RecordAssignment(context.Background(), record))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -144,20 +145,20 @@ func (r *contactRepository) FindAll(ctx context.Context, filter types.ContactFil
 	argIndex++
 
 	if filter.Name != nil && *filter.Name != "" {
-		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Name+"%")
+		conditions = append(conditions, database.ILike("name", argIndex))
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Email != nil && *filter.Email != "" {
-		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Email+"%")
+		conditions = append(conditions, database.ILike("email", argIndex))
+		args = append(args, database.LikePattern(*filter.Email, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Phone != nil && *filter.Phone != "" {
-		conditions = append(conditions, fmt.Sprintf("phone ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Phone+"%")
+		conditions = append(conditions, database.ILike("phone", argIndex))
+		args = append(args, database.LikePattern(*filter.Phone, filter.MatchMode))
 		argIndex++
 	}
 
@@ -596,20 +597,20 @@ func (r *contactRepository) Count(ctx context.Context, filter types.ContactFilte
 	argIndex++
 
 	if filter.Name != nil && *filter.Name != "" {
-		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Name+"%")
+		conditions = append(conditions, database.ILike("name", argIndex))
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Email != nil && *filter.Email != "" {
-		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Email+"%")
+		conditions = append(conditions, database.ILike("email", argIndex))
+		args = append(args, database.LikePattern(*filter.Email, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Phone != nil && *filter.Phone != "" {
-		conditions = append(conditions, fmt.Sprintf("phone ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Phone+"%")
+		conditions = append(conditions, database.ILike("phone", argIndex))
+		args = append(args, database.LikePattern(*filter.Phone, filter.MatchMode))
 		argIndex++
 	}
 
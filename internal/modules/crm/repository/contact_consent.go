@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type contactConsentRepository struct {
+	db *sql.DB
+}
+
+func NewContactConsentRepository(db *sql.DB) types.ContactConsentRepository {
+	return &contactConsentRepository{db: db}
+}
+
+const contactConsentColumns = `organization_id, contact_id, channel, status, source, opted_in_at, confirmation_sent_at,
+	confirmed_at, opted_out_at, COALESCE(confirmation_token_hash, ''), updated_by, updated_at`
+
+const contactConsentEventColumns = `id, organization_id, contact_id, channel, COALESCE(previous_status, 'unknown'), status,
+	source, actor_id, COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(note, ''), created_at`
+
+func scanContactConsent(row rowScanner) (*types.ContactConsent, error) {
+	var c types.ContactConsent
+	err := row.Scan(&c.OrganizationID, &c.ContactID, &c.Channel, &c.Status, &c.Source, &c.OptedInAt, &c.ConfirmationSentAt,
+		&c.ConfirmedAt, &c.OptedOutAt, &c.ConfirmationTokenHash, &c.UpdatedBy, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanContactConsentEvent(row rowScanner) (*types.ContactConsentEvent, error) {
+	var e types.ContactConsentEvent
+	err := row.Scan(&e.ID, &e.OrganizationID, &e.ContactID, &e.Channel, &e.PreviousStatus, &e.Status,
+		&e.Source, &e.ActorID, &e.IPAddress, &e.UserAgent, &e.Note, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *contactConsentRepository) ListConsents(ctx context.Context, orgID, contactID uuid.UUID) ([]types.ContactConsent, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT true FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`,
+		contactID, orgID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("contact not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to find contact: %w", err)
+	}
+
+	query := `SELECT ` + contactConsentColumns + ` FROM contact_consents
+		WHERE organization_id = $1 AND contact_id = $2`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, contactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []types.ContactConsent{}
+	for rows.Next() {
+		consent, err := scanContactConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact consent: %w", err)
+		}
+		consents = append(consents, *consent)
+	}
+	return consents, rows.Err()
+}
+
+// insertConsentEvent records event with the status the change replaced
+func insertConsentEvent(ctx context.Context, tx *sql.Tx, event types.ContactConsentEvent, previous *types.ContactConsentStatus) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO contact_consent_events (
+			id, organization_id, contact_id, channel, previous_status, status,
+			source, actor_id, ip_address, user_agent, note, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12)`,
+		event.ID, event.OrganizationID, event.ContactID, event.Channel, previous, event.Status,
+		event.Source, event.ActorID, event.IPAddress, event.UserAgent, event.Note, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record consent change: %w", err)
+	}
+	return nil
+}
+
+func (r *contactConsentRepository) SaveConsent(ctx context.Context, c types.ContactConsent, event types.ContactConsentEvent) (*types.ContactConsent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The contact's row serializes changes so each event has the status it
+	// replaced
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT true FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		c.ContactID, c.OrganizationID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("contact not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to lock contact: %w", err)
+	}
+
+	var previous *types.ContactConsentStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM contact_consents WHERE contact_id = $1 AND channel = $2`,
+		c.ContactID, c.Channel).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read contact consent: %w", err)
+	}
+
+	query := `
+		INSERT INTO contact_consents (
+			organization_id, contact_id, channel, status, source, opted_in_at, confirmation_sent_at,
+			confirmed_at, opted_out_at, confirmation_token_hash, updated_by, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
+		ON CONFLICT (contact_id, channel) DO UPDATE SET
+			status = EXCLUDED.status,
+			source = EXCLUDED.source,
+			opted_in_at = EXCLUDED.opted_in_at,
+			confirmation_sent_at = EXCLUDED.confirmation_sent_at,
+			confirmed_at = EXCLUDED.confirmed_at,
+			opted_out_at = EXCLUDED.opted_out_at,
+			confirmation_token_hash = EXCLUDED.confirmation_token_hash,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + contactConsentColumns
+
+	saved, err := scanContactConsent(tx.QueryRowContext(ctx, query,
+		c.OrganizationID, c.ContactID, c.Channel, c.Status, c.Source, c.OptedInAt, c.ConfirmationSentAt,
+		c.ConfirmedAt, c.OptedOutAt, c.ConfirmationTokenHash, c.UpdatedBy, event.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save contact consent: %w", err)
+	}
+	if err := insertConsentEvent(ctx, tx, event, previous); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit contact consent: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *contactConsentRepository) ConfirmConsent(ctx context.Context, tokenHash string, sentAfter time.Time, event types.ContactConsentEvent) (*types.ContactConsent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE contact_consents SET status = 'opted_in', source = $3, opted_in_at = $4, confirmed_at = $4,
+			confirmation_token_hash = NULL, updated_by = NULL, updated_at = $4
+		WHERE confirmation_token_hash = $1 AND status = 'pending' AND confirmation_sent_at > $2
+		RETURNING ` + contactConsentColumns
+
+	confirmed, err := scanContactConsent(tx.QueryRowContext(ctx, query, tokenHash, sentAfter, event.Source, event.CreatedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrConsentTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to confirm contact consent: %w", err)
+	}
+
+	event.OrganizationID, event.ContactID, event.Channel = confirmed.OrganizationID, confirmed.ContactID, confirmed.Channel
+	pending := types.ContactConsentPending
+	if err := insertConsentEvent(ctx, tx, event, &pending); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit contact consent: %w", err)
+	}
+	return confirmed, nil
+}
+
+func (r *contactConsentRepository) ListConsentEvents(ctx context.Context, orgID, contactID uuid.UUID) ([]types.ContactConsentEvent, error) {
+	query := `SELECT ` + contactConsentEventColumns + ` FROM contact_consent_events
+		WHERE organization_id = $1 AND contact_id = $2
+		ORDER BY created_at DESC, id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, contactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact consent history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []types.ContactConsentEvent{}
+	for rows.Next() {
+		event, err := scanContactConsentEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact consent event: %w", err)
+		}
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+// UnsubscribeToken only writes the contact the first time, so reading the
+// token does not touch the contact's updated_at
+func (r *contactConsentRepository) UnsubscribeToken(ctx context.Context, orgID, contactID uuid.UUID, token string) (string, error) {
+	var current sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT unsubscribe_token FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`,
+		contactID, orgID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("contact not found: %w", err)
+		}
+		return "", fmt.Errorf("failed to read unsubscribe token: %w", err)
+	}
+	if current.Valid {
+		return current.String, nil
+	}
+
+	// A concurrent call may set it first; its token is then the one kept
+	query := `UPDATE contacts SET unsubscribe_token = COALESCE(unsubscribe_token, $3)
+		WHERE id = $1 AND organization_id = $2
+		RETURNING unsubscribe_token`
+	if err := r.db.QueryRowContext(ctx, query, contactID, orgID, token).Scan(&current); err != nil {
+		return "", fmt.Errorf("failed to set unsubscribe token: %w", err)
+	}
+	return current.String, nil
+}
+
+func (r *contactConsentRepository) FindUnsubscribeToken(ctx context.Context, token string) (uuid.UUID, uuid.UUID, error) {
+	query := `SELECT organization_id, id FROM contacts WHERE unsubscribe_token = $1 AND deleted_at IS NULL`
+
+	var orgID, contactID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, token).Scan(&orgID, &contactID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, uuid.Nil, types.ErrConsentTokenNotFound
+		}
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to find unsubscribe token: %w", err)
+	}
+	return orgID, contactID, nil
+}
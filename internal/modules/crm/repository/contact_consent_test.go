@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var contactConsentRowColumns = []string{"organization_id", "contact_id", "channel", "status", "source", "opted_in_at",
+	"confirmation_sent_at", "confirmed_at", "opted_out_at", "confirmation_token_hash", "updated_by", "updated_at"}
+
+func TestSaveConsentRecordsTheStatusItReplaces(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, contactID, userID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+	consent := types.ContactConsent{OrganizationID: orgID, ContactID: contactID, Channel: types.ContactConsentEmail,
+		Status: types.ContactConsentOptedOut, Source: types.ContactConsentSourceUser, OptedOutAt: &now, UpdatedBy: &userID}
+	event := types.ContactConsentEvent{ID: uuid.New(), OrganizationID: orgID, ContactID: contactID, Channel: types.ContactConsentEmail,
+		Status: types.ContactConsentOptedOut, Source: types.ContactConsentSourceUser, ActorID: &userID, CreatedAt: now}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT true FROM contacts[\s\S]+FOR UPDATE`).
+		WithArgs(contactID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT status FROM contact_consents`).
+		WithArgs(contactID, types.ContactConsentEmail).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("opted_in"))
+	mock.ExpectQuery(`INSERT INTO contact_consents[\s\S]+ON CONFLICT \(contact_id, channel\) DO UPDATE`).
+		WithArgs(orgID, contactID, types.ContactConsentEmail, types.ContactConsentOptedOut, types.ContactConsentSourceUser,
+			nil, nil, nil, &now, "", &userID, now).
+		WillReturnRows(sqlmock.NewRows(contactConsentRowColumns).
+			AddRow(orgID, contactID, "email", "opted_out", "user", nil, nil, nil, now, "", userID, now))
+	mock.ExpectExec(`INSERT INTO contact_consent_events`).
+		WithArgs(event.ID, orgID, contactID, types.ContactConsentEmail, sqlmock.AnyArg(), types.ContactConsentOptedOut,
+			types.ContactConsentSourceUser, &userID, "", "", "", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	saved, err := NewContactConsentRepository(db).SaveConsent(context.Background(), consent, event)
+	require.NoError(t, err)
+	assert.Equal(t, types.ContactConsentOptedOut, saved.Status)
+	assert.False(t, saved.Allows())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmConsentRefusesUnknownOrExpiredTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sentAfter := time.Now().Add(-7 * 24 * time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE contact_consents SET status = 'opted_in'[\s\S]+status = 'pending' AND confirmation_sent_at > \$2`).
+		WithArgs("hash", sentAfter, types.ContactConsentSourceDoubleOptIn, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(contactConsentRowColumns))
+	mock.ExpectRollback()
+
+	_, err = NewContactConsentRepository(db).ConfirmConsent(context.Background(), "hash", sentAfter,
+		types.ContactConsentEvent{ID: uuid.New(), Source: types.ContactConsentSourceDoubleOptIn, CreatedAt: time.Now()})
+	assert.ErrorIs(t, err, types.ErrConsentTokenNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,322 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type contactGDPRRepository struct {
+	db *sql.DB
+}
+
+func NewContactGDPRRepository(db *sql.DB) types.ContactGDPRRepository {
+	return &contactGDPRRepository{db: db}
+}
+
+const contactGDPRRequestColumns = `id, organization_id, contact_id, kind, status, COALESCE(reason, ''), requested_by,
+	requested_at, reviewed_by, reviewed_at, COALESCE(review_note, ''), completed_at, summary, COALESCE(sha256, '')`
+
+// gdprInternalColumns are left out of exported rows: search indexes derived
+// from the other columns, and secrets
+const gdprInternalColumns = `ARRAY['search_vector', 'search_embedding', 'search_embedding_384', 'search_embedding_512',
+	'search_embedding_768', 'search_embedding_1024', 'search_embedding_1536', 'password_hash']::text[]`
+
+// gdprSectionQuery aggregates the rows selected by %s into a JSON array
+// and counts them
+const gdprSectionQuery = `SELECT COALESCE(jsonb_agg(to_jsonb(t) - ` + gdprInternalColumns + ` ORDER BY t.created_at, t.id), '[]'), count(*)
+	FROM (%s) t`
+
+// contactLeads selects the IDs of the contact's leads. $1 is the contact and
+// $2 its organization, as in every statement below.
+const contactLeads = `SELECT id FROM leads WHERE organization_id = $2 AND contact_id = $1`
+
+// contactGDPRSections select the rows of each export section
+var contactGDPRSections = map[string]string{
+	"contact": `SELECT * FROM contacts WHERE organization_id = $2 AND id = $1`,
+	"addresses": `SELECT * FROM contacts
+		WHERE organization_id = $2 AND id <> $1 AND deleted_at IS NULL AND (parent_id = $1
+			OR id IN (SELECT partner_shipping_id FROM sales_orders WHERE organization_id = $2 AND partner_id = $1)
+			OR id IN (SELECT partner_shipping_id FROM invoices WHERE organization_id = $2 AND partner_id = $1))`,
+	"leads": `SELECT * FROM leads WHERE organization_id = $2 AND contact_id = $1`,
+	"activities": `SELECT * FROM activities
+		WHERE organization_id = $2 AND ((res_model = 'contacts' AND res_id = $1)
+			OR (res_model = 'leads' AND res_id IN (` + contactLeads + `)))`,
+	"emails": `SELECT * FROM lead_email_messages WHERE organization_id = $2 AND lead_id IN (` + contactLeads + `)`,
+	"invoices": `SELECT * FROM invoices
+		WHERE organization_id = $2 AND (partner_id = $1 OR commercial_partner_id = $1)`,
+	"sales_orders": `SELECT * FROM sales_orders
+		WHERE organization_id = $2 AND (partner_id = $1 OR partner_invoice_id = $1 OR partner_shipping_id = $1)`,
+	"delivery_stops":  `SELECT * FROM delivery_route_stops WHERE organization_id = $2 AND contact_id = $1`,
+	"portal_account":  `SELECT * FROM portal_accounts WHERE organization_id = $2 AND contact_id = $1`,
+	"support_tickets": `SELECT * FROM support_tickets WHERE organization_id = $2 AND contact_id = $1`,
+	"return_requests": `SELECT * FROM return_requests WHERE organization_id = $2 AND contact_id = $1`,
+	"consents":        `SELECT * FROM contact_consents WHERE organization_id = $2 AND contact_id = $1`,
+	"consent_history": `SELECT * FROM contact_consent_events WHERE organization_id = $2 AND contact_id = $1`,
+}
+
+// contactPseudonym replaces the names of an anonymized contact
+const contactPseudonym = `'Anonymized ' || left($1::uuid::text, 8)`
+
+// anonymizedContact clears the personal data of a contact row. Company tax
+// IDs are kept as they identify a business, not a person.
+const anonymizedContact = `name = ` + contactPseudonym + `, display_name = ` + contactPseudonym + `,
+	email = NULL, phone = NULL, mobile = NULL, fax = NULL, website = NULL, title = NULL, job_position = NULL,
+	street = NULL, street2 = NULL, city = NULL, zip = NULL, image_url = NULL, comment = NULL,
+	tax_id = CASE WHEN is_company THEN tax_id END, custom_fields = '{}', metadata = '{}',
+	search_embedding = NULL, search_embedding_384 = NULL, search_embedding_512 = NULL, search_embedding_768 = NULL,
+	search_embedding_1024 = NULL, search_embedding_1536 = NULL, anonymized_at = now(), updated_at = now()`
+
+// contactAnonymizations pseudonymize the personal data held about a
+// contact, keyed by the name they are counted under. Amounts, dates and
+// references of invoices and orders are left alone so the books still
+// balance; only the contact details copied onto invoices are replaced.
+var contactAnonymizations = []struct {
+	name  string
+	query string
+}{
+	{"contact", `UPDATE contacts SET ` + anonymizedContact + ` WHERE organization_id = $2 AND id = $1`},
+	{"addresses", `UPDATE contacts SET ` + anonymizedContact + ` WHERE organization_id = $2 AND parent_id = $1`},
+	{"leads", `UPDATE leads SET name = ` + contactPseudonym + `, contact_name = NULL, email = NULL, phone = NULL,
+			mobile = NULL, street = NULL, street2 = NULL, city = NULL, zip = NULL, website = NULL, description = NULL,
+			custom_fields = '{}', metadata = '{}', updated_at = now(), version = version + 1
+		WHERE organization_id = $2 AND contact_id = $1`},
+	{"activities", `UPDATE activities SET summary = 'Anonymized', note = NULL, updated_at = now()
+		WHERE organization_id = $2 AND ((res_model = 'contacts' AND res_id = $1)
+			OR (res_model = 'leads' AND res_id IN (` + contactLeads + `)))`},
+	{"emails", `UPDATE lead_email_messages SET from_email = 'anonymized', from_name = NULL, subject = '',
+			body_text = NULL, body_html = NULL
+		WHERE organization_id = $2 AND lead_id IN (` + contactLeads + `)`},
+	{"invoices", `UPDATE invoices SET invoice_partner_display_name = ` + contactPseudonym + `,
+			invoice_source_email = NULL, updated_at = now()
+		WHERE organization_id = $2 AND (partner_id = $1 OR commercial_partner_id = $1)`},
+	{"delivery_stops", `UPDATE delivery_route_stops SET address = NULL, notes = NULL, updated_at = now()
+		WHERE organization_id = $2 AND contact_id = $1`},
+	{"support_tickets", `UPDATE support_tickets SET subject = 'Anonymized', description = 'Anonymized', updated_at = now()
+		WHERE organization_id = $2 AND contact_id = $1`},
+	{"return_requests", `UPDATE return_requests SET reason = 'Anonymized', updated_at = now()
+		WHERE organization_id = $2 AND contact_id = $1`},
+	{"consent_history", `UPDATE contact_consent_events SET ip_address = NULL, user_agent = NULL, note = NULL
+		WHERE organization_id = $2 AND contact_id = $1`},
+	{"portal_account", `DELETE FROM portal_accounts WHERE organization_id = $2 AND contact_id = $1`},
+	{"duplicates", `DELETE FROM contact_duplicates
+		WHERE organization_id = $2 AND (contact_id_1 = $1 OR contact_id_2 = $1)`},
+}
+
+func scanContactGDPRRequest(row rowScanner) (*types.ContactGDPRRequest, error) {
+	var req types.ContactGDPRRequest
+	var summary []byte
+	err := row.Scan(&req.ID, &req.OrganizationID, &req.ContactID, &req.Kind, &req.Status, &req.Reason,
+		&req.RequestedBy, &req.RequestedAt, &req.ReviewedBy, &req.ReviewedAt, &req.ReviewNote,
+		&req.CompletedAt, &summary, &req.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(summary, &req.Summary); err != nil {
+		return nil, fmt.Errorf("failed to decode request summary: %w", err)
+	}
+	return &req, nil
+}
+
+// ExportSections reads every section in one snapshot so the archive is
+// consistent
+func (r *contactGDPRRepository) ExportSections(ctx context.Context, orgID, contactID uuid.UUID) ([]types.ContactGDPRSection, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT true FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`,
+		contactID, orgID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contact not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+
+	sections := make([]types.ContactGDPRSection, 0, len(types.ContactGDPRSections))
+	for _, name := range types.ContactGDPRSections {
+		section := types.ContactGDPRSection{Name: name}
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(gdprSectionQuery, contactGDPRSections[name]), contactID, orgID).
+			Scan(&section.Data, &section.Rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", name, err)
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func (r *contactGDPRRepository) AnonymizedAt(ctx context.Context, orgID, contactID uuid.UUID) (*time.Time, error) {
+	var anonymizedAt *time.Time
+	err := r.db.QueryRowContext(ctx, `
+		SELECT anonymized_at FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+	`, contactID, orgID).Scan(&anonymizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contact not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	return anonymizedAt, nil
+}
+
+func (r *contactGDPRRepository) CreateRequest(ctx context.Context, req *types.ContactGDPRRequest) error {
+	summary, err := json.Marshal(req.Summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode request summary: %w", err)
+	}
+	if req.Summary == nil {
+		summary = []byte(`{}`)
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO contact_gdpr_requests (
+			id, organization_id, contact_id, kind, status, reason, requested_by, completed_at, summary, sha256
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, ''))
+		RETURNING requested_at
+	`, req.ID, req.OrganizationID, req.ContactID, req.Kind, req.Status, req.Reason, req.RequestedBy,
+		req.CompletedAt, summary, req.SHA256).Scan(&req.RequestedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return types.ErrContactGDPRRequestPending
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create gdpr request: %w", err)
+	}
+	return nil
+}
+
+func (r *contactGDPRRepository) PendingAnonymization(ctx context.Context, orgID, contactID uuid.UUID) (*types.ContactGDPRRequest, error) {
+	req, err := scanContactGDPRRequest(r.db.QueryRowContext(ctx, `
+		SELECT `+contactGDPRRequestColumns+`
+		FROM contact_gdpr_requests
+		WHERE organization_id = $1 AND contact_id = $2 AND kind = 'anonymize' AND status = 'pending'
+	`, orgID, contactID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("pending anonymization not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending anonymization: %w", err)
+	}
+	return req, nil
+}
+
+func (r *contactGDPRRepository) ListRequests(ctx context.Context, orgID, contactID uuid.UUID) ([]types.ContactGDPRRequest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+contactGDPRRequestColumns+`
+		FROM contact_gdpr_requests
+		WHERE organization_id = $1 AND contact_id = $2
+		ORDER BY requested_at DESC, id
+	`, orgID, contactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gdpr requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []types.ContactGDPRRequest{}
+	for rows.Next() {
+		req, err := scanContactGDPRRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan gdpr request: %w", err)
+		}
+		requests = append(requests, *req)
+	}
+	return requests, rows.Err()
+}
+
+// Anonymize locks the pending request and its contact, pseudonymizes the
+// contact everywhere it is held and completes the request with the count
+// of rows changed in each table
+func (r *contactGDPRRepository) Anonymize(ctx context.Context, orgID, requestID, reviewedBy uuid.UUID, note string) (*types.ContactGDPRRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var contactID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT contact_id FROM contact_gdpr_requests
+		WHERE id = $1 AND organization_id = $2 AND kind = 'anonymize' AND status = 'pending'
+		FOR UPDATE
+	`, requestID, orgID).Scan(&contactID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("pending anonymization not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock gdpr request: %w", err)
+	}
+
+	var anonymizedAt *time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT anonymized_at FROM contacts WHERE id = $1 AND organization_id = $2
+		FOR UPDATE
+	`, contactID, orgID).Scan(&anonymizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("contact not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock contact: %w", err)
+	}
+	if anonymizedAt != nil {
+		return nil, types.ErrContactAnonymized
+	}
+
+	summary := make(map[string]int64, len(contactAnonymizations))
+	for _, step := range contactAnonymizations {
+		res, err := tx.ExecContext(ctx, step.query, contactID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to anonymize %s: %w", step.name, err)
+		}
+		if summary[step.name], err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to count anonymized %s: %w", step.name, err)
+		}
+	}
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request summary: %w", err)
+	}
+
+	req, err := scanContactGDPRRequest(tx.QueryRowContext(ctx, `
+		UPDATE contact_gdpr_requests SET status = 'completed', reviewed_by = $3, reviewed_at = now(),
+			review_note = NULLIF($4, ''), completed_at = now(), summary = $5
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+contactGDPRRequestColumns,
+		requestID, orgID, reviewedBy, note, encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete gdpr request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return req, nil
+}
+
+func (r *contactGDPRRepository) Reject(ctx context.Context, orgID, requestID, reviewedBy uuid.UUID, note string) (*types.ContactGDPRRequest, error) {
+	req, err := scanContactGDPRRequest(r.db.QueryRowContext(ctx, `
+		UPDATE contact_gdpr_requests SET status = 'rejected', reviewed_by = $3, reviewed_at = now(), review_note = $4
+		WHERE id = $1 AND organization_id = $2 AND kind = 'anonymize' AND status = 'pending'
+		RETURNING `+contactGDPRRequestColumns,
+		requestID, orgID, reviewedBy, note))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("pending anonymization not found: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject gdpr request: %w", err)
+	}
+	return req, nil
+}
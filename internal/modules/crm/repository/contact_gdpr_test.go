@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func expectGDPRLocks(mock sqlmock.Sqlmock, orgID, requestID, contactID uuid.UUID, anonymizedAt interface{}) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT contact_id FROM contact_gdpr_requests[\s\S]+status = 'pending'\s+FOR UPDATE`).
+		WithArgs(requestID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"contact_id"}).AddRow(contactID))
+	mock.ExpectQuery(`SELECT anonymized_at FROM contacts[\s\S]+FOR UPDATE`).
+		WithArgs(contactID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"anonymized_at"}).AddRow(anonymizedAt))
+}
+
+func TestAnonymizePseudonymizesEveryTableInOneTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, requestID, contactID, reviewer, requester := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	expectGDPRLocks(mock, orgID, requestID, contactID, nil)
+	for i, step := range contactAnonymizations {
+		mock.ExpectExec(`^`+regexp.QuoteMeta(step.query)+`$`).
+			WithArgs(contactID, orgID).
+			WillReturnResult(sqlmock.NewResult(0, int64(i)))
+	}
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE contact_gdpr_requests SET status = 'completed'`).
+		WithArgs(requestID, orgID, reviewer, "Identity verified", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "contact_id", "kind", "status", "reason",
+			"requested_by", "requested_at", "reviewed_by", "reviewed_at", "review_note", "completed_at", "summary", "sha256"}).
+			AddRow(requestID, orgID, contactID, "anonymize", "completed", "Erasure request", requester, now,
+				reviewer, now, "Identity verified", now, []byte(`{"contact": 0, "leads": 2}`), ""))
+	mock.ExpectCommit()
+
+	req, err := NewContactGDPRRepository(db).Anonymize(context.Background(), orgID, requestID, reviewer, "Identity verified")
+	require.NoError(t, err)
+	assert.Equal(t, types.ContactGDPRStatusCompleted, req.Status)
+	assert.Equal(t, int64(2), req.Summary["leads"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnonymizeRefusesAnonymizedContact(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, requestID, contactID := uuid.New(), uuid.New(), uuid.New()
+	anonymizedAt := time.Now().Add(-time.Hour)
+	expectGDPRLocks(mock, orgID, requestID, contactID, anonymizedAt)
+	mock.ExpectRollback()
+
+	_, err = NewContactGDPRRepository(db).Anonymize(context.Background(), orgID, requestID, uuid.New(), "")
+	assert.ErrorIs(t, err, types.ErrContactAnonymized)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
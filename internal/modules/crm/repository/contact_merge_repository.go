@@ -3,21 +3,62 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
-	"github.com/texttheater/golang-levenshtein/levenshtein"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 )
 
+// Duplicate scores are out of 100: an exact email match and a matching
+// normalized phone number add fixed weights and the trigram similarity of
+// the names adds up to duplicateNameWeight
+const (
+	duplicateEmailWeight = 40
+	duplicatePhoneWeight = 30
+	duplicateNameWeight  = 30
+
+	// nameMatchSimilarity is the trigram similarity from which names are
+	// reported as a matching field
+	nameMatchSimilarity = 0.6
+)
+
+// duplicateScore scores a candidate pair from its email_match, phone_match
+// and name_similarity columns, the same way CalculateSimilarity does
+var duplicateScore = fmt.Sprintf(
+	"(CASE WHEN email_match THEN %d ELSE 0 END + CASE WHEN phone_match THEN %d ELSE 0 END + round(name_similarity * %d))",
+	duplicateEmailWeight, duplicatePhoneWeight, duplicateNameWeight,
+)
+
+// contactReferences re-point the records of a merged contact to the
+// surviving one, keyed by the name they are counted under.
+// $1 = surviving contact, $2 = merged contact, $3 = organization
+var contactReferences = []struct {
+	name  string
+	query string
+}{
+	{"leads", `UPDATE leads SET contact_id = $1, updated_at = now(), version = version + 1
+		WHERE organization_id = $3 AND contact_id = $2`},
+	{"invoices", `UPDATE invoices SET
+			partner_id = CASE WHEN partner_id = $2 THEN $1 ELSE partner_id END,
+			commercial_partner_id = CASE WHEN commercial_partner_id = $2 THEN $1 ELSE commercial_partner_id END,
+			updated_at = now()
+		WHERE organization_id = $3 AND (partner_id = $2 OR commercial_partner_id = $2)`},
+	{"activities", `UPDATE activities SET res_id = $1, updated_at = now()
+		WHERE organization_id = $3 AND res_model = 'contacts' AND res_id = $2`},
+}
+
 // ContactMergeRepository handles duplicate detection and contact merging operations
 type ContactMergeRepository interface {
 	// Duplicate Detection
 	FindPotentialDuplicates(ctx context.Context, orgID uuid.UUID, threshold int, limit int) ([]*types.ContactDuplicate, error)
+	FindDuplicatesOf(ctx context.Context, orgID, contactID uuid.UUID, threshold int, limit int) ([]*types.ContactDuplicateMatch, error)
 	CreateDuplicate(ctx context.Context, duplicate *types.ContactDuplicate) error
 	GetDuplicate(ctx context.Context, id uuid.UUID) (*types.ContactDuplicate, error)
 	UpdateDuplicateStatus(ctx context.Context, id uuid.UUID, status string, reviewedBy uuid.UUID) error
@@ -25,7 +66,7 @@ type ContactMergeRepository interface {
 	CountDuplicates(ctx context.Context, filter types.DuplicateFilter) (int, error)
 
 	// Merging
-	MergeContacts(ctx context.Context, masterID, duplicateID uuid.UUID, fieldSelections map[string]string, mergedBy uuid.UUID, orgID uuid.UUID) error
+	MergeContacts(ctx context.Context, orgID, masterID, duplicateID uuid.UUID, strategy string, fieldSelections map[string]string, mergedBy uuid.UUID, dryRun bool) (*types.ContactMergeResult, error)
 	GetMergeHistory(ctx context.Context, contactID uuid.UUID) ([]*types.ContactMergeHistory, error)
 
 	// Similarity Calculation
@@ -40,149 +81,231 @@ func NewContactMergeRepository(db *sql.DB) ContactMergeRepository {
 	return &contactMergeRepository{db: db}
 }
 
-// FindPotentialDuplicates identifies potential duplicate contacts based on similarity scoring
+// FindPotentialDuplicates finds the duplicate pairs of an organization's
+// contacts scoring at least threshold. Candidates are pairs sharing an
+// email or normalized phone number, or with trigram-similar names; each
+// comes from its own join so the equality and trigram indexes can be used.
+// A limit of 0 returns every pair.
 func (r *contactMergeRepository) FindPotentialDuplicates(ctx context.Context, orgID uuid.UUID, threshold int, limit int) ([]*types.ContactDuplicate, error) {
-	// Get all active contacts for the organization
 	query := `
-		SELECT id, email, phone, name
-		FROM contacts
-		WHERE organization_id = $1 AND deleted_at IS NULL
-		ORDER BY created_at DESC
+		WITH normalized AS (
+			SELECT id, name,
+				NULLIF(lower(trim(email)), '') AS email,
+				NULLIF(regexp_replace(phone, '[^0-9]', '', 'g'), '') AS phone
+			FROM contacts
+			WHERE organization_id = $1 AND deleted_at IS NULL
+		), candidates AS (
+			SELECT a.id AS id1, b.id AS id2
+			FROM normalized a JOIN normalized b ON a.email = b.email AND a.id < b.id
+			UNION
+			SELECT a.id, b.id
+			FROM normalized a JOIN normalized b ON a.phone = b.phone AND a.id < b.id
+			UNION
+			SELECT a.id, b.id
+			FROM contacts a JOIN contacts b ON a.name % b.name AND a.id < b.id
+			WHERE a.organization_id = $1 AND a.deleted_at IS NULL
+				AND b.organization_id = $1 AND b.deleted_at IS NULL
+		), pairs AS (
+			SELECT c.id1, c.id2,
+				COALESCE(a.email = b.email, false) AS email_match,
+				COALESCE(a.phone = b.phone, false) AS phone_match,
+				similarity(a.name, b.name) AS name_similarity
+			FROM candidates c
+			JOIN normalized a ON a.id = c.id1
+			JOIN normalized b ON b.id = c.id2
+		)
+		SELECT id1, id2, email_match, phone_match, name_similarity, score
+		FROM (SELECT *, ` + duplicateScore + ` AS score FROM pairs) scored
+		WHERE score >= $2
+		ORDER BY score DESC, id1, id2
+		LIMIT NULLIF($3, 0)
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, orgID)
+	rows, err := r.db.QueryContext(ctx, query, orgID, threshold, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query contacts: %w", err)
+		return nil, fmt.Errorf("failed to query duplicate contacts: %w", err)
 	}
 	defer rows.Close()
 
-	var contacts []*types.Contact
+	var duplicates []*types.ContactDuplicate
 	for rows.Next() {
-		contact := &types.Contact{}
-		err := rows.Scan(
-			&contact.ID,
-			&contact.Email,
-			&contact.Phone,
-			&contact.Name,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		duplicate := &types.ContactDuplicate{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			Status:         "pending",
 		}
-		contacts = append(contacts, contact)
-	}
-
-	// Compare all contact pairs to find duplicates
-	var duplicates []*types.ContactDuplicate
-	seen := make(map[string]bool) // Track unique pairs to avoid duplicates
-
-	for i := 0; i < len(contacts); i++ {
-		for j := i + 1; j < len(contacts); j++ {
-			similarity := r.CalculateSimilarity(contacts[i], contacts[j])
-
-			if similarity >= threshold {
-				// Create unique key for pair (smaller UUID first)
-				contact1ID := contacts[i].ID.String()
-				contact2ID := contacts[j].ID.String()
-				if contact1ID > contact2ID {
-					contact1ID, contact2ID = contact2ID, contact1ID
-				}
-				pairKey := contact1ID + "-" + contact2ID
-
-				if !seen[pairKey] {
-					duplicate := &types.ContactDuplicate{
-						ID:              uuid.New(),
-						OrganizationID:  orgID,
-						ContactID1:      contacts[i].ID,
-						ContactID2:      contacts[j].ID,
-						SimilarityScore: float64(similarity),
-						Status:          "pending",
-						MatchingFields:  r.getMatchingFields(contacts[i], contacts[j]),
-					}
-					duplicates = append(duplicates, duplicate)
-					seen[pairKey] = true
-
-					if limit > 0 && len(duplicates) >= limit {
-						return duplicates, nil
-					}
-				}
-			}
+		var emailMatch, phoneMatch bool
+		var nameSimilarity float64
+		if err := rows.Scan(
+			&duplicate.ContactID1,
+			&duplicate.ContactID2,
+			&emailMatch,
+			&phoneMatch,
+			&nameSimilarity,
+			&duplicate.SimilarityScore,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate contacts: %w", err)
 		}
+		duplicate.MatchingFields = matchingFields(emailMatch, phoneMatch, nameSimilarity)
+		duplicates = append(duplicates, duplicate)
 	}
 
-	return duplicates, nil
+	return duplicates, rows.Err()
 }
 
-// CalculateSimilarity computes a similarity score (0-100) between two contacts
-func (r *contactMergeRepository) CalculateSimilarity(contact1, contact2 *types.Contact) int {
-	score := 0
+// FindDuplicatesOf finds the contacts that may duplicate contactID, best
+// match first. It returns sql.ErrNoRows when the contact does not exist.
+func (r *contactMergeRepository) FindDuplicatesOf(ctx context.Context, orgID, contactID uuid.UUID, threshold int, limit int) ([]*types.ContactDuplicateMatch, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT true FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`,
+		contactID, orgID,
+	).Scan(&exists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("contact not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+
+	query := `
+		WITH target AS (
+			SELECT name,
+				NULLIF(lower(trim(email)), '') AS email,
+				NULLIF(regexp_replace(phone, '[^0-9]', '', 'g'), '') AS phone
+			FROM contacts
+			WHERE id = $2
+		), pairs AS (
+			SELECT c.id, c.name, c.email, c.phone,
+				COALESCE(lower(trim(c.email)) = t.email, false) AS email_match,
+				COALESCE(regexp_replace(c.phone, '[^0-9]', '', 'g') = t.phone, false) AS phone_match,
+				similarity(c.name, t.name) AS name_similarity
+			FROM contacts c, target t
+			WHERE c.organization_id = $1 AND c.deleted_at IS NULL AND c.id <> $2
+				AND (lower(trim(c.email)) = t.email
+					OR regexp_replace(c.phone, '[^0-9]', '', 'g') = t.phone
+					OR c.name % t.name)
+		)
+		SELECT id, name, email, phone, email_match, phone_match, name_similarity, score
+		FROM (SELECT *, ` + duplicateScore + ` AS score FROM pairs) scored
+		WHERE score >= $3
+		ORDER BY score DESC, name
+		LIMIT NULLIF($4, 0)
+	`
 
-	// Email exact match: +40 points
-	if contact1.Email != nil && contact2.Email != nil && strings.EqualFold(*contact1.Email, *contact2.Email) {
-		score += 40
+	rows, err := r.db.QueryContext(ctx, query, orgID, contactID, threshold, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate contacts: %w", err)
 	}
+	defer rows.Close()
 
-	// Phone exact match: +30 points
-	if contact1.Phone != nil && contact2.Phone != nil {
-		phone1 := normalizePhone(*contact1.Phone)
-		phone2 := normalizePhone(*contact2.Phone)
-		if phone1 == phone2 {
-			score += 30
+	var matches []*types.ContactDuplicateMatch
+	for rows.Next() {
+		match := &types.ContactDuplicateMatch{}
+		var emailMatch, phoneMatch bool
+		if err := rows.Scan(
+			&match.ContactID,
+			&match.Name,
+			&match.Email,
+			&match.Phone,
+			&emailMatch,
+			&phoneMatch,
+			&match.NameSimilarity,
+			&match.SimilarityScore,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate contact: %w", err)
 		}
+		match.MatchingFields = matchingFields(emailMatch, phoneMatch, match.NameSimilarity)
+		matches = append(matches, match)
 	}
 
-	// Name similarity: 0-30 points (using Levenshtein distance)
-	name1 := strings.ToLower(strings.TrimSpace(contact1.Name))
-	name2 := strings.ToLower(strings.TrimSpace(contact2.Name))
+	return matches, rows.Err()
+}
 
-	if name1 != "" && name2 != "" {
-		nameScore := calculateStringSimilarity(name1, name2)
-		score += int(float64(nameScore) * 0.30) // Scale to 30 points max
+// CalculateSimilarity computes a similarity score (0-100) between two
+// contacts, weighting fields as the duplicate queries do
+func (r *contactMergeRepository) CalculateSimilarity(contact1, contact2 *types.Contact) int {
+	emailMatch, phoneMatch, nameSimilarity := compareContacts(contact1, contact2)
+
+	score := int(math.Round(nameSimilarity * duplicateNameWeight))
+	if emailMatch {
+		score += duplicateEmailWeight
+	}
+	if phoneMatch {
+		score += duplicatePhoneWeight
 	}
 
 	return score
 }
 
-// getMatchingFields returns a list of fields that match between two contacts
-func (r *contactMergeRepository) getMatchingFields(contact1, contact2 *types.Contact) []string {
-	var matching []string
+// compareContacts reports whether two contacts share an email address and
+// a normalized phone number, and how similar their names are
+func compareContacts(contact1, contact2 *types.Contact) (emailMatch, phoneMatch bool, nameSimilarity float64) {
+	if contact1.Email != nil && contact2.Email != nil {
+		email1 := strings.TrimSpace(*contact1.Email)
+		emailMatch = email1 != "" && strings.EqualFold(email1, strings.TrimSpace(*contact2.Email))
+	}
 
-	if contact1.Email != nil && contact2.Email != nil && strings.EqualFold(*contact1.Email, *contact2.Email) {
-		matching = append(matching, "email")
+	if contact1.Phone != nil && contact2.Phone != nil {
+		phone1 := normalizePhone(*contact1.Phone)
+		phoneMatch = phone1 != "" && phone1 == normalizePhone(*contact2.Phone)
 	}
 
-	if contact1.Phone != nil && contact2.Phone != nil && normalizePhone(*contact1.Phone) == normalizePhone(*contact2.Phone) {
+	return emailMatch, phoneMatch, trigramSimilarity(contact1.Name, contact2.Name)
+}
+
+// matchingFields lists the fields on which a duplicate pair matched
+func matchingFields(emailMatch, phoneMatch bool, nameSimilarity float64) []string {
+	matching := []string{}
+
+	if emailMatch {
+		matching = append(matching, "email")
+	}
+	if phoneMatch {
 		matching = append(matching, "phone")
 	}
-
-	if strings.EqualFold(strings.TrimSpace(contact1.Name), strings.TrimSpace(contact2.Name)) {
+	if nameSimilarity >= nameMatchSimilarity {
 		matching = append(matching, "name")
 	}
 
 	return matching
 }
 
-// CreateDuplicate stores a detected duplicate pair
+// CreateDuplicate stores a detected duplicate pair. The pair is stored
+// with the lower contact ID first; a pair that is already stored is left
+// untouched and reported as sql.ErrNoRows.
 func (r *contactMergeRepository) CreateDuplicate(ctx context.Context, duplicate *types.ContactDuplicate) error {
+	if duplicate.ContactID1.String() > duplicate.ContactID2.String() {
+		duplicate.ContactID1, duplicate.ContactID2 = duplicate.ContactID2, duplicate.ContactID1
+	}
+
+	matchingFields, err := json.Marshal(duplicate.MatchingFields)
+	if err != nil {
+		return fmt.Errorf("failed to encode matching fields: %w", err)
+	}
+
 	query := `
 		INSERT INTO contact_duplicates (
 			id, organization_id, contact_id_1, contact_id_2,
 			similarity_score, matching_fields, status, created_at
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (organization_id, contact_id_1, contact_id_2) DO NOTHING
 		RETURNING created_at
 	`
 
-	now := time.Now()
-	err := r.db.QueryRowContext(ctx, query,
+	err = r.db.QueryRowContext(ctx, query,
 		duplicate.ID,
 		duplicate.OrganizationID,
 		duplicate.ContactID1,
 		duplicate.ContactID2,
 		duplicate.SimilarityScore,
-		pq.Array(duplicate.MatchingFields),
+		matchingFields,
 		duplicate.Status,
-		now,
+		time.Now(),
 	).Scan(&duplicate.CreatedAt)
-
+	if err == sql.ErrNoRows {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create duplicate: %w", err)
 	}
@@ -200,31 +323,14 @@ func (r *contactMergeRepository) GetDuplicate(ctx context.Context, id uuid.UUID)
 		WHERE id = $1
 	`
 
-	duplicate := &types.ContactDuplicate{}
-	var matchingFields pq.StringArray
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&duplicate.ID,
-		&duplicate.OrganizationID,
-		&duplicate.ContactID1,
-		&duplicate.ContactID2,
-		&duplicate.SimilarityScore,
-		&matchingFields,
-		&duplicate.Status,
-		&duplicate.ReviewedBy,
-		&duplicate.ReviewedAt,
-		&duplicate.Notes,
-		&duplicate.CreatedAt,
-	)
-
+	duplicate, err := scanDuplicate(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("duplicate not found")
+		return nil, fmt.Errorf("duplicate not found: %w", err)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get duplicate: %w", err)
 	}
 
-	duplicate.MatchingFields = []string(matchingFields)
 	return duplicate, nil
 }
 
@@ -248,7 +354,7 @@ func (r *contactMergeRepository) UpdateDuplicateStatus(ctx context.Context, id u
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("duplicate not found")
+		return fmt.Errorf("duplicate not found: %w", sql.ErrNoRows)
 	}
 
 	return nil
@@ -279,6 +385,12 @@ func (r *contactMergeRepository) ListDuplicates(ctx context.Context, filter type
 		argPos++
 	}
 
+	if filter.ContactID != nil {
+		query += fmt.Sprintf(" AND (contact_id_1 = $%d OR contact_id_2 = $%d)", argPos, argPos)
+		args = append(args, *filter.ContactID)
+		argPos++
+	}
+
 	query += " ORDER BY similarity_score DESC, created_at DESC"
 
 	if filter.Limit > 0 {
@@ -294,31 +406,14 @@ func (r *contactMergeRepository) ListDuplicates(ctx context.Context, filter type
 
 	var duplicates []*types.ContactDuplicate
 	for rows.Next() {
-		duplicate := &types.ContactDuplicate{}
-		var matchingFields pq.StringArray
-
-		err := rows.Scan(
-			&duplicate.ID,
-			&duplicate.OrganizationID,
-			&duplicate.ContactID1,
-			&duplicate.ContactID2,
-			&duplicate.SimilarityScore,
-			&matchingFields,
-			&duplicate.Status,
-			&duplicate.ReviewedBy,
-			&duplicate.ReviewedAt,
-			&duplicate.Notes,
-			&duplicate.CreatedAt,
-		)
+		duplicate, err := scanDuplicate(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan duplicate: %w", err)
 		}
-
-		duplicate.MatchingFields = []string(matchingFields)
 		duplicates = append(duplicates, duplicate)
 	}
 
-	return duplicates, nil
+	return duplicates, rows.Err()
 }
 
 // CountDuplicates counts duplicates matching the filter
@@ -336,6 +431,12 @@ func (r *contactMergeRepository) CountDuplicates(ctx context.Context, filter typ
 	if filter.MinSimilarity != nil {
 		query += fmt.Sprintf(" AND similarity_score >= $%d", argPos)
 		args = append(args, *filter.MinSimilarity)
+		argPos++
+	}
+
+	if filter.ContactID != nil {
+		query += fmt.Sprintf(" AND (contact_id_1 = $%d OR contact_id_2 = $%d)", argPos, argPos)
+		args = append(args, *filter.ContactID)
 	}
 
 	var count int
@@ -347,60 +448,146 @@ func (r *contactMergeRepository) CountDuplicates(ctx context.Context, filter typ
 	return count, nil
 }
 
-// MergeContacts merges two contacts, keeping the master and archiving the duplicate
-func (r *contactMergeRepository) MergeContacts(ctx context.Context, masterID, duplicateID uuid.UUID, fieldSelections map[string]string, mergedBy uuid.UUID, orgID uuid.UUID) error {
+// MergeContacts merges the duplicate into the master contact in a single
+// transaction: the master takes the selected fields, the duplicate's leads,
+// invoices, activities and relationships are re-pointed to the master, the
+// duplicate is soft deleted and the merge is recorded in the merge history.
+//
+// With dryRun the same statements run and are rolled back, so the result
+// previews exactly what the merge would change.
+func (r *contactMergeRepository) MergeContacts(ctx context.Context, orgID, masterID, duplicateID uuid.UUID, strategy string, fieldSelections map[string]string, mergedBy uuid.UUID, dryRun bool) (*types.ContactMergeResult, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Get both contacts
-	masterContact, err := r.getContactInTx(ctx, tx, masterID)
+	// Lock both contacts so neither changes while references move
+	masterContact, err := r.getContactInTx(ctx, tx, orgID, masterID)
 	if err != nil {
-		return fmt.Errorf("failed to get master contact: %w", err)
+		return nil, fmt.Errorf("failed to get master contact: %w", err)
 	}
 
-	duplicateContact, err := r.getContactInTx(ctx, tx, duplicateID)
+	duplicateContact, err := r.getContactInTx(ctx, tx, orgID, duplicateID)
 	if err != nil {
-		return fmt.Errorf("failed to get duplicate contact: %w", err)
+		return nil, fmt.Errorf("failed to get duplicate contact: %w", err)
 	}
 
-	// Build merged contact based on field selections
-	mergedData := r.buildMergedContact(masterContact, duplicateContact, fieldSelections)
+	result := &types.ContactMergeResult{
+		MasterContactID:    masterID,
+		DuplicateContactID: duplicateID,
+		DryRun:             dryRun,
+		Fields:             r.buildMergedContact(masterContact, duplicateContact, fieldSelections),
+		Reassigned:         make(map[string]int64, len(contactReferences)+1),
+	}
 
-	// Update master contact with merged data
-	err = r.updateContactInTx(ctx, tx, masterID, mergedData)
-	if err != nil {
-		return fmt.Errorf("failed to update master contact: %w", err)
+	if err := r.updateContactInTx(ctx, tx, masterID, result.Fields); err != nil {
+		return nil, fmt.Errorf("failed to update master contact: %w", err)
+	}
+
+	for _, ref := range contactReferences {
+		res, err := tx.ExecContext(ctx, ref.query, masterID, duplicateID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reassign %s: %w", ref.name, err)
+		}
+		if result.Reassigned[ref.name], err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to count reassigned %s: %w", ref.name, err)
+		}
+	}
+
+	if err := r.reassignRelationshipsInTx(ctx, tx, orgID, masterID, duplicateID, result); err != nil {
+		return nil, err
 	}
 
 	// Soft delete duplicate contact
 	_, err = tx.ExecContext(ctx, `UPDATE contacts SET deleted_at = $1 WHERE id = $2`, time.Now(), duplicateID)
 	if err != nil {
-		return fmt.Errorf("failed to delete duplicate contact: %w", err)
+		return nil, fmt.Errorf("failed to delete duplicate contact: %w", err)
+	}
+
+	// Resolve the detected pair, if any
+	contactID1, contactID2 := masterID, duplicateID
+	if contactID1.String() > contactID2.String() {
+		contactID1, contactID2 = contactID2, contactID1
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE contact_duplicates SET status = 'merged', reviewed_by = $1, reviewed_at = $2
+		WHERE organization_id = $3 AND contact_id_1 = $4 AND contact_id_2 = $5
+	`, mergedBy, time.Now(), orgID, contactID1, contactID2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve duplicate: %w", err)
+	}
+
+	if dryRun {
+		return result, nil
 	}
 
 	// Create merge history record
 	historyID := uuid.New()
-	now := time.Now()
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO contact_merge_history (
 			id, organization_id, master_contact_id, merged_contact_ids,
 			merge_strategy, merged_by, merged_at, field_selections, can_undo
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`,
-		historyID, orgID, masterID, []uuid.UUID{duplicateID}, "manual",
-		mergedBy, now, convertStringMapToJSONBMap(fieldSelections), true,
+		historyID, orgID, masterID, pq.Array([]string{duplicateID.String()}), strategy,
+		mergedBy, time.Now(), convertStringMapToJSONBMap(fieldSelections), false,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create merge history: %w", err)
+		return nil, fmt.Errorf("failed to create merge history: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.MergeHistoryID = &historyID
+	return result, nil
+}
+
+// reassignRelationshipsInTx moves the duplicate's relationships to the
+// master. Relationships between the two contacts, and those the master
+// already has with the same contact and type, are removed instead.
+func (r *contactMergeRepository) reassignRelationshipsInTx(ctx context.Context, tx *sql.Tx, orgID, masterID, duplicateID uuid.UUID, result *types.ContactMergeResult) error {
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM contact_relationships d
+		WHERE d.organization_id = $3
+			AND (
+				(d.contact_id = $2 AND d.related_contact_id = $1)
+				OR (d.contact_id = $1 AND d.related_contact_id = $2)
+				OR (d.contact_id = $2 AND EXISTS (
+					SELECT 1 FROM contact_relationships m
+					WHERE m.organization_id = $3 AND m.contact_id = $1
+						AND m.related_contact_id = d.related_contact_id AND m.type = d.type))
+				OR (d.related_contact_id = $2 AND EXISTS (
+					SELECT 1 FROM contact_relationships m
+					WHERE m.organization_id = $3 AND m.related_contact_id = $1
+						AND m.contact_id = d.contact_id AND m.type = d.type))
+			)
+	`, masterID, duplicateID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to remove conflicting relationships: %w", err)
+	}
+	if result.RelationshipsRemoved, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to count removed relationships: %w", err)
 	}
 
+	var moved int64
+	for _, column := range []string{"contact_id", "related_contact_id"} {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE contact_relationships SET %[1]s = $1, updated_at = now() WHERE organization_id = $3 AND %[1]s = $2`, column,
+		), masterID, duplicateID, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to reassign relationships: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count reassigned relationships: %w", err)
+		}
+		moved += n
+	}
+	result.Reassigned["relationships"] = moved
+
 	return nil
 }
 
@@ -423,11 +610,12 @@ func (r *contactMergeRepository) GetMergeHistory(ctx context.Context, contactID
 	var history []*types.ContactMergeHistory
 	for rows.Next() {
 		h := &types.ContactMergeHistory{}
+		var mergedIDs pq.StringArray
 		err := rows.Scan(
 			&h.ID,
 			&h.OrganizationID,
 			&h.MasterContactID,
-			pq.Array(&h.MergedContactIDs),
+			&mergedIDs,
 			&h.MergeStrategy,
 			&h.MergedBy,
 			&h.MergedAt,
@@ -438,30 +626,68 @@ func (r *contactMergeRepository) GetMergeHistory(ctx context.Context, contactID
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan merge history: %w", err)
 		}
+		for _, id := range mergedIDs {
+			mergedID, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse merged contact id: %w", err)
+			}
+			h.MergedContactIDs = append(h.MergedContactIDs, mergedID)
+		}
 		history = append(history, h)
 	}
 
-	return history, nil
+	return history, rows.Err()
 }
 
 // Helper functions
 
-func (r *contactMergeRepository) getContactInTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (map[string]interface{}, error) {
+func scanDuplicate(row interface{ Scan(...interface{}) error }) (*types.ContactDuplicate, error) {
+	duplicate := &types.ContactDuplicate{}
+	var matchingFields []byte
+
+	err := row.Scan(
+		&duplicate.ID,
+		&duplicate.OrganizationID,
+		&duplicate.ContactID1,
+		&duplicate.ContactID2,
+		&duplicate.SimilarityScore,
+		&matchingFields,
+		&duplicate.Status,
+		&duplicate.ReviewedBy,
+		&duplicate.ReviewedAt,
+		&duplicate.Notes,
+		&duplicate.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matchingFields) > 0 {
+		if err := json.Unmarshal(matchingFields, &duplicate.MatchingFields); err != nil {
+			return nil, fmt.Errorf("failed to decode matching fields: %w", err)
+		}
+	}
+
+	return duplicate, nil
+}
+
+func (r *contactMergeRepository) getContactInTx(ctx context.Context, tx *sql.Tx, orgID, id uuid.UUID) (map[string]interface{}, error) {
 	query := `
 		SELECT email, phone, name, street, city, state_id, country_id
 		FROM contacts
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+		FOR UPDATE
 	`
 
 	var email, phone, name, street, city *string
 	var stateID, countryID *uuid.UUID
 
-	err := tx.QueryRowContext(ctx, query, id).Scan(
+	err := tx.QueryRowContext(ctx, query, id, orgID).Scan(
 		&email, &phone, &name, &street, &city, &stateID, &countryID,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("contact not found")
+		return nil, fmt.Errorf("contact not found: %w", err)
 	}
 	if err != nil {
 		return nil, err
@@ -495,12 +721,16 @@ func (r *contactMergeRepository) updateContactInTx(ctx context.Context, tx *sql.
 	return err
 }
 
+// buildMergedContact keeps the master's values, takes those selected from
+// the duplicate, and fills fields the master lacks from the duplicate
 func (r *contactMergeRepository) buildMergedContact(master, duplicate map[string]interface{}, selections map[string]string) map[string]interface{} {
 	merged := make(map[string]interface{})
 
-	// Default strategy: keep master values unless selection specifies otherwise
 	for key, value := range master {
 		merged[key] = value
+		if _, selected := selections[key]; !selected && isEmptyField(value) {
+			merged[key] = duplicate[key]
+		}
 	}
 
 	// Apply field selections
@@ -510,12 +740,24 @@ func (r *contactMergeRepository) buildMergedContact(master, duplicate map[string
 				merged[field] = val
 			}
 		}
-		// "master" or default: already in merged from master copy
+		// "master": already in merged from master copy
 	}
 
 	return merged
 }
 
+// isEmptyField reports whether a contact field read by getContactInTx is unset
+func isEmptyField(value interface{}) bool {
+	switch v := value.(type) {
+	case *string:
+		return v == nil || strings.TrimSpace(*v) == ""
+	case *uuid.UUID:
+		return v == nil
+	default:
+		return value == nil
+	}
+}
+
 // Utility functions
 
 func normalizePhone(phone string) string {
@@ -529,44 +771,37 @@ func normalizePhone(phone string) string {
 	return digits.String()
 }
 
-func getStringValue(ptr *string) string {
-	if ptr == nil {
-		return ""
-	}
-	return *ptr
-}
-
-func calculateStringSimilarity(s1, s2 string) int {
-	if s1 == s2 {
-		return 100
-	}
-
-	distance := levenshtein.DistanceForStrings([]rune(s1), []rune(s2), levenshtein.DefaultOptions)
-	maxLen := max(len(s1), len(s2))
-
-	if maxLen == 0 {
-		return 100
+// trigramSimilarity computes the similarity of two strings as pg_trgm's
+// similarity() does: the share of trigrams the two have in common, where
+// each lowercased word is padded with two leading and one trailing space
+func trigramSimilarity(s1, s2 string) float64 {
+	t1, t2 := trigrams(s1), trigrams(s2)
+	if len(t1) == 0 || len(t2) == 0 {
+		return 0
 	}
 
-	similarity := 100 - (distance * 100 / maxLen)
-	if similarity < 0 {
-		return 0
+	shared := 0
+	for t := range t1 {
+		if _, ok := t2[t]; ok {
+			shared++
+		}
 	}
 
-	return similarity
+	return float64(shared) / float64(len(t1)+len(t2)-shared)
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
 	}
-	return b
-}
-
-func convertMapToJSON(m map[string]interface{}) string {
-	// Simple JSON serialization for snapshot storage
-	// In production, use json.Marshal
-	return fmt.Sprintf("%v", m)
+	return set
 }
 
 func convertStringMapToJSONBMap(m map[string]string) types.JSONBMap {
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var mergeContactColumns = []string{"email", "phone", "name", "street", "city", "state_id", "country_id"}
+
+func expectMergeStatements(mock sqlmock.Sqlmock, orgID, masterID, duplicateID uuid.UUID) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM contacts\s+WHERE id = \$1 AND organization_id = \$2 AND deleted_at IS NULL\s+FOR UPDATE`).
+		WithArgs(masterID, orgID).
+		WillReturnRows(sqlmock.NewRows(mergeContactColumns).
+			AddRow("ada@example.com", nil, "Ada Lovelace", nil, "London", nil, nil))
+	mock.ExpectQuery(`FOR UPDATE`).
+		WithArgs(duplicateID, orgID).
+		WillReturnRows(sqlmock.NewRows(mergeContactColumns).
+			AddRow("ada@example.com", "+44 20 7946 0000", "Ada  Lovelace", "12 St James's Sq", "Paris", nil, nil))
+	// The master keeps its city and takes the phone and street it lacks
+	mock.ExpectExec(`UPDATE contacts SET\s+email = \$1`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), masterID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE leads SET contact_id = \$1`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE invoices SET`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE activities SET res_id = \$1`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`DELETE FROM contact_relationships`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE contact_relationships SET contact_id = \$1`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE contact_relationships SET related_contact_id = \$1`).
+		WithArgs(masterID, duplicateID, orgID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE contacts SET deleted_at = \$1 WHERE id = \$2`).
+		WithArgs(sqlmock.AnyArg(), duplicateID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE contact_duplicates SET status = 'merged'`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMergeContactsDryRunRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, masterID, duplicateID := uuid.New(), uuid.New(), uuid.New()
+	expectMergeStatements(mock, orgID, masterID, duplicateID)
+	mock.ExpectRollback()
+
+	result, err := NewContactMergeRepository(db).MergeContacts(context.Background(),
+		orgID, masterID, duplicateID, "keep_master", map[string]string{}, uuid.New(), true)
+	require.NoError(t, err)
+
+	assert.True(t, result.DryRun)
+	assert.Nil(t, result.MergeHistoryID)
+	assert.Equal(t, map[string]int64{"leads": 2, "invoices": 3, "activities": 4, "relationships": 2}, result.Reassigned)
+	assert.Equal(t, int64(1), result.RelationshipsRemoved)
+	assert.Equal(t, "London", *result.Fields["city"].(*string))
+	assert.Equal(t, "+44 20 7946 0000", *result.Fields["phone"].(*string))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeContactsRecordsHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, masterID, duplicateID := uuid.New(), uuid.New(), uuid.New()
+	expectMergeStatements(mock, orgID, masterID, duplicateID)
+	mock.ExpectExec(`INSERT INTO contact_merge_history`).
+		WithArgs(sqlmock.AnyArg(), orgID, masterID, sqlmock.AnyArg(), "custom",
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := NewContactMergeRepository(db).MergeContacts(context.Background(),
+		orgID, masterID, duplicateID, "custom", map[string]string{"city": "duplicate"}, uuid.New(), false)
+	require.NoError(t, err)
+
+	assert.False(t, result.DryRun)
+	assert.NotNil(t, result.MergeHistoryID)
+	assert.Equal(t, "Paris", *result.Fields["city"].(*string))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCalculateSimilarity(t *testing.T) {
+	email, otherEmail := "Ada@Example.com", "ada@example.com "
+	phone, otherPhone := "+44 (20) 7946-0000", "442079460000"
+
+	repo := &contactMergeRepository{}
+	tests := []struct {
+		name     string
+		contact1 *types.Contact
+		contact2 *types.Contact
+		want     int
+	}{
+		{"all fields", &types.Contact{Name: "Ada Lovelace", Email: &email, Phone: &phone},
+			&types.Contact{Name: "ada lovelace", Email: &otherEmail, Phone: &otherPhone}, 100},
+		{"phone only", &types.Contact{Name: "Ada Lovelace", Phone: &phone},
+			&types.Contact{Name: "Charles Babbage", Phone: &otherPhone}, 30},
+		{"similar name", &types.Contact{Name: "Jonathan Smith"},
+			&types.Contact{Name: "Jonathon Smith"}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, repo.CalculateSimilarity(tt.contact1, tt.contact2))
+		})
+	}
+}
+
+func TestTrigramSimilarityMatchesPgTrgm(t *testing.T) {
+	// Values from the pg_trgm documentation and similarity() in PostgreSQL
+	assert.InDelta(t, 0.363636, trigramSimilarity("word", "two words"), 0.0001)
+	assert.InDelta(t, 1.0, trigramSimilarity("Ada Lovelace", "ada-lovelace"), 0.0001)
+	assert.Zero(t, trigramSimilarity("", "Ada"))
+}
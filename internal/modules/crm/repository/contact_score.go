@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type contactScoreRepository struct {
+	db *sql.DB
+}
+
+func NewContactScoreRepository(db *sql.DB) types.ContactScoreRepository {
+	return &contactScoreRepository{db: db}
+}
+
+const contactScoreWeightColumns = `organization_id, recency_weight, frequency_weight, response_rate_weight,
+	won_deals_weight, updated_at, updated_by`
+
+const contactScoreSnapshotColumns = `contact_id, organization_id, scored_on, engagement_score, lead_score,
+	recency_score, frequency_score, response_score, won_deals_score, last_activity_at, calculated_at`
+
+// contactSignalsQuery aggregates the activities logged on the target
+// contacts and on their leads, and their leads' outcomes. Activities count
+// from when they were done, or logged when still planned; inbound emails
+// are the activities of ingested lead emails. %s selects the targets.
+// $1 is the moment of scoring and $2 the start of the scoring window.
+const contactSignalsQuery = `
+	WITH target AS (%s),
+	records AS (
+		SELECT t.id AS contact_id, 'contacts' AS res_model, t.id AS res_id FROM target t
+		UNION ALL
+		SELECT t.id, 'leads', l.id
+		FROM target t
+		JOIN leads l ON l.contact_id = t.id AND l.deleted_at IS NULL
+	),
+	acts AS (
+		SELECT r.contact_id, a.activity_type, COALESCE(a.done_date, a.created_at) AS happened_at,
+			EXISTS (SELECT 1 FROM lead_email_messages m WHERE m.activity_id = a.id) AS inbound
+		FROM records r
+		JOIN activities a ON a.res_model = r.res_model AND a.res_id = r.res_id
+		WHERE a.state <> 'cancelled' AND COALESCE(a.done_date, a.created_at) <= $1
+	),
+	stats AS (
+		SELECT contact_id,
+			MAX(happened_at) AS last_activity_at,
+			COUNT(*) FILTER (WHERE happened_at > $2) AS activities,
+			COUNT(*) FILTER (WHERE happened_at > $2 AND activity_type = 'email' AND NOT inbound) AS emails_sent,
+			COUNT(*) FILTER (WHERE happened_at > $2 AND inbound) AS emails_received
+		FROM acts
+		GROUP BY contact_id
+	)
+	SELECT t.id, t.organization_id, s.last_activity_at, COALESCE(s.activities, 0),
+		COALESCE(s.emails_sent, 0), COALESCE(s.emails_received, 0),
+		COALESCE(d.won, 0), COALESCE(d.open_probability, 0)
+	FROM target t
+	LEFT JOIN stats s ON s.contact_id = t.id
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) FILTER (WHERE status = 'won') AS won,
+			MAX(probability) FILTER (WHERE status IN ('new', 'in_progress')) AS open_probability
+		FROM leads
+		WHERE contact_id = t.id AND deleted_at IS NULL
+	) d ON true
+	ORDER BY t.id`
+
+func scanContactScoreWeights(row rowScanner) (*types.ContactScoreWeights, error) {
+	var weights types.ContactScoreWeights
+	err := row.Scan(&weights.OrganizationID, &weights.Recency, &weights.Frequency, &weights.ResponseRate,
+		&weights.WonDeals, &weights.UpdatedAt, &weights.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &weights, nil
+}
+
+func scanContactScoreSnapshot(row rowScanner) (*types.ContactScoreSnapshot, error) {
+	var snapshot types.ContactScoreSnapshot
+	err := row.Scan(&snapshot.ContactID, &snapshot.OrganizationID, &snapshot.ScoredOn, &snapshot.EngagementScore,
+		&snapshot.LeadScore, &snapshot.RecencyScore, &snapshot.FrequencyScore, &snapshot.ResponseScore,
+		&snapshot.WonDealsScore, &snapshot.LastActivityAt, &snapshot.CalculatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func scanContactSignals(row rowScanner, at time.Time) (*types.ContactEngagementSignals, error) {
+	signals := types.ContactEngagementSignals{At: at}
+	err := row.Scan(&signals.ContactID, &signals.OrganizationID, &signals.LastActivityAt, &signals.Activities,
+		&signals.EmailsSent, &signals.EmailsReceived, &signals.WonDeals, &signals.OpenLeadProbability)
+	if err != nil {
+		return nil, err
+	}
+	return &signals, nil
+}
+
+func (r *contactScoreRepository) Weights(ctx context.Context, orgID uuid.UUID) (*types.ContactScoreWeights, error) {
+	query := `SELECT ` + contactScoreWeightColumns + ` FROM contact_score_weights WHERE organization_id = $1`
+
+	weights, err := scanContactScoreWeights(r.db.QueryRowContext(ctx, query, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		defaults := types.DefaultContactScoreWeights(orgID)
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contact score weights: %w", err)
+	}
+	return weights, nil
+}
+
+func (r *contactScoreRepository) SaveWeights(ctx context.Context, weights types.ContactScoreWeights) (*types.ContactScoreWeights, error) {
+	query := `
+		INSERT INTO contact_score_weights (` + contactScoreWeightColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			recency_weight = EXCLUDED.recency_weight,
+			frequency_weight = EXCLUDED.frequency_weight,
+			response_rate_weight = EXCLUDED.response_rate_weight,
+			won_deals_weight = EXCLUDED.won_deals_weight,
+			updated_at = EXCLUDED.updated_at,
+			updated_by = EXCLUDED.updated_by
+		RETURNING ` + contactScoreWeightColumns
+
+	saved, err := scanContactScoreWeights(r.db.QueryRowContext(ctx, query,
+		weights.OrganizationID, weights.Recency, weights.Frequency, weights.ResponseRate,
+		weights.WonDeals, weights.UpdatedAt, weights.UpdatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save contact score weights: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *contactScoreRepository) Signals(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID, at time.Time, window time.Duration) (*types.ContactEngagementSignals, error) {
+	query := fmt.Sprintf(contactSignalsQuery, `
+		SELECT id, organization_id FROM contacts
+		WHERE id = $3 AND organization_id = $4 AND deleted_at IS NULL`)
+
+	signals, err := scanContactSignals(r.db.QueryRowContext(ctx, query, at, at.Add(-window), contactID, orgID), at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("contact not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to query contact engagement: %w", err)
+	}
+	return signals, nil
+}
+
+func (r *contactScoreRepository) SignalsBatch(ctx context.Context, after uuid.UUID, limit int, at time.Time, window time.Duration) ([]types.ContactEngagementSignals, error) {
+	query := fmt.Sprintf(contactSignalsQuery, `
+		SELECT id, organization_id FROM contacts
+		WHERE id > $3 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $4`)
+
+	rows, err := r.db.QueryContext(ctx, query, at, at.Add(-window), after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact engagement: %w", err)
+	}
+	defer rows.Close()
+
+	batch := []types.ContactEngagementSignals{}
+	for rows.Next() {
+		signals, err := scanContactSignals(rows, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact engagement: %w", err)
+		}
+		batch = append(batch, *signals)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contact engagement: %w", err)
+	}
+
+	return batch, nil
+}
+
+func (r *contactScoreRepository) SaveSnapshots(ctx context.Context, snapshots []types.ContactScoreSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO contact_score_history (`+contactScoreSnapshotColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (contact_id, scored_on) DO UPDATE SET
+			engagement_score = EXCLUDED.engagement_score,
+			lead_score = EXCLUDED.lead_score,
+			recency_score = EXCLUDED.recency_score,
+			frequency_score = EXCLUDED.frequency_score,
+			response_score = EXCLUDED.response_score,
+			won_deals_score = EXCLUDED.won_deals_score,
+			last_activity_at = EXCLUDED.last_activity_at,
+			calculated_at = EXCLUDED.calculated_at`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare contact score insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range snapshots {
+		_, err := stmt.ExecContext(ctx, s.ContactID, s.OrganizationID, s.ScoredOn, s.EngagementScore,
+			s.LeadScore, s.RecencyScore, s.FrequencyScore, s.ResponseScore, s.WonDealsScore,
+			s.LastActivityAt, s.CalculatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save contact score: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit contact scores: %w", err)
+	}
+	return nil
+}
+
+func (r *contactScoreRepository) History(ctx context.Context, filter types.ContactScoreHistoryFilter) ([]types.ContactScoreSnapshot, error) {
+	query := `
+		SELECT ` + contactScoreSnapshotColumns + `
+		FROM contact_score_history
+		WHERE contact_id = $1 AND organization_id = $2 AND scored_on BETWEEN $3 AND $4
+		ORDER BY scored_on`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.ContactID, filter.OrganizationID, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact score history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []types.ContactScoreSnapshot{}
+	for rows.Next() {
+		snapshot, err := scanContactScoreSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact score: %w", err)
+		}
+		history = append(history, *snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contact score history: %w", err)
+	}
+
+	return history, nil
+}
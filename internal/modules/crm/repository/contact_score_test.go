@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var contactSignalColumns = []string{"id", "organization_id", "last_activity_at", "activities",
+	"emails_sent", "emails_received", "won", "open_probability"}
+
+func TestSignalsBatchPagesThroughContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	after, contactID, orgID := uuid.New(), uuid.New(), uuid.New()
+	lastActivity := at.Add(-48 * time.Hour)
+
+	mock.ExpectQuery(`WHERE id > \$3 AND deleted_at IS NULL\s+ORDER BY id\s+LIMIT \$4`).
+		WithArgs(at, at.Add(-90*24*time.Hour), after, 500).
+		WillReturnRows(sqlmock.NewRows(contactSignalColumns).
+			AddRow(contactID.String(), orgID.String(), lastActivity, 7, 2, 1, 1, 40))
+
+	batch, err := NewContactScoreRepository(db).SignalsBatch(context.Background(), after, 500, at, 90*24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, types.ContactEngagementSignals{
+		ContactID: contactID, OrganizationID: orgID, At: at, LastActivityAt: &lastActivity,
+		Activities: 7, EmailsSent: 2, EmailsReceived: 1, WonDeals: 1, OpenLeadProbability: 40,
+	}, batch[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWeightsDefaultWhenUnset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID := uuid.New()
+	mock.ExpectQuery(`FROM contact_score_weights WHERE organization_id = \$1`).
+		WithArgs(orgID).
+		WillReturnError(sql.ErrNoRows)
+
+	weights, err := NewContactScoreRepository(db).Weights(context.Background(), orgID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DefaultContactScoreWeights(orgID), *weights)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveSnapshotsReplacesScoresOfTheDay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	day := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.ContactScoreSnapshot{
+		{ContactID: uuid.New(), OrganizationID: uuid.New(), ScoredOn: day, EngagementScore: 64, CalculatedAt: day},
+		{ContactID: uuid.New(), OrganizationID: uuid.New(), ScoredOn: day, EngagementScore: 12, CalculatedAt: day},
+	}
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare(`ON CONFLICT \(contact_id, scored_on\) DO UPDATE`)
+	for _, s := range snapshots {
+		prepared.ExpectExec().
+			WithArgs(s.ContactID, s.OrganizationID, day, s.EngagementScore, 0, 0, 0, nil, 0, nil, day).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	require.NoError(t, NewContactScoreRepository(db).SaveSnapshots(context.Background(), snapshots))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+type contactSearchRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewContactSearchRepository(db *sql.DB, dialect database.Dialect) types.ContactSearchRepository {
+	return &contactSearchRepository{db: db, dialect: database.DialectOrDefault(dialect)}
+}
+
+// compileContactSearch translates an advanced filter into a WHERE clause and
+// its arguments, written with $n placeholders. Search and Count share it so
+// that the page and the total agree.
+func compileContactSearch(filter types.AdvancedContactFilter, dialect database.Dialect) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	add := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	add("organization_id = $%d", filter.OrganizationID)
+
+	if filter.SearchQuery != "" {
+		pattern := database.LikePattern(filter.SearchQuery, filter.MatchMode)
+		var matches []string
+		for _, column := range []string{"name", "email", "phone"} {
+			args = append(args, pattern)
+			matches = append(matches, dialect.ILike(column, len(args)))
+		}
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	// Contacts with any of the tags
+	if len(filter.Tags) > 0 {
+		var tags []string
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+			tags = append(tags, fmt.Sprintf("$%d = ANY(tags)", len(args)))
+		}
+		conditions = append(conditions, "("+strings.Join(tags, " OR ")+")")
+	}
+
+	if filter.ScoreRange.Min > 0 {
+		add("engagement_score >= $%d", filter.ScoreRange.Min)
+	}
+	if filter.ScoreRange.Max > 0 {
+		add("engagement_score <= $%d", filter.ScoreRange.Max)
+	}
+	if !filter.LastContacted.From.IsZero() {
+		add("last_contacted_at >= $%d", filter.LastContacted.From)
+	}
+	if !filter.LastContacted.To.IsZero() {
+		add("last_contacted_at <= $%d", filter.LastContacted.To)
+	}
+
+	if filter.Condition != nil {
+		var condition string
+		condition, args = filter.Condition.Render(dialect, args)
+		conditions = append(conditions, condition)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r *contactSearchRepository) Search(ctx context.Context, filter types.AdvancedContactFilter) ([]*types.Contact, error) {
+	where, args := compileContactSearch(filter, r.dialect)
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`SELECT id, organization_id, name, email, phone, is_customer, is_vendor,
+		street, city, state_id, country_id, created_at, updated_at, deleted_at
+		FROM contacts WHERE %s
+		ORDER BY updated_at DESC, id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	query, args = r.dialect.Rebind(query, args)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []*types.Contact
+	for rows.Next() {
+		var contact types.Contact
+		if err := rows.Scan(
+			&contact.ID,
+			&contact.OrganizationID,
+			&contact.Name,
+			&contact.Email,
+			&contact.Phone,
+			&contact.IsCustomer,
+			&contact.IsVendor,
+			&contact.Street,
+			&contact.City,
+			&contact.StateID,
+			&contact.CountryID,
+			&contact.CreatedAt,
+			&contact.UpdatedAt,
+			&contact.DeletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		contacts = append(contacts, &contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during contact iteration: %w", err)
+	}
+
+	return contacts, nil
+}
+
+func (r *contactSearchRepository) Count(ctx context.Context, filter types.AdvancedContactFilter) (int, error) {
+	where, args := compileContactSearch(filter, r.dialect)
+	query, args := r.dialect.Rebind("SELECT COUNT(*) FROM contacts WHERE "+where, args)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count contacts: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+)
+
+func TestContactSearchPagesAndCountsTheSameContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, tagID := uuid.New(), uuid.New()
+	condition, err := query.Compile(query.Node{Field: "lead_score", Op: query.OpGte, Value: []byte(`60`)}, types.ContactQuerySchema, nil)
+	require.NoError(t, err)
+	filter := types.AdvancedContactFilter{OrganizationID: orgID, SearchQuery: "ada", MatchMode: database.MatchModePrefix,
+		Tags: []string{tagID.String()}, Condition: condition, Page: 3, PageSize: 10}
+	filter.ScoreRange.Min = 40
+
+	where := `deleted_at IS NULL AND organization_id = \$1 AND \(name ILIKE \$2 ESCAPE '\\' OR email ILIKE \$3 ESCAPE '\\' OR phone ILIKE \$4 ESCAPE '\\'\)` +
+		` AND \(\$5 = ANY\(tags\)\) AND engagement_score >= \$6 AND lead_score >= \$7`
+	args := []driver.Value{orgID, "ada%", "ada%", "ada%", tagID.String(), 40, 60.0}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, organization_id, name[\s\S]+FROM contacts WHERE ` + where + `\s+ORDER BY updated_at DESC, id\s+LIMIT \$8 OFFSET \$9`).
+		WithArgs(append(args, 10, 20)...).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "name", "email", "phone", "is_customer", "is_vendor",
+			"street", "city", "state_id", "country_id", "created_at", "updated_at", "deleted_at"}).
+			AddRow(uuid.New(), orgID, "Ada Lovelace", nil, nil, true, false, nil, nil, nil, nil, now, now, nil))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM contacts WHERE ` + where + `$`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+
+	repo := NewContactSearchRepository(db, database.Postgres)
+	contacts, err := repo.Search(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, contacts, 1)
+	assert.Equal(t, "Ada Lovelace", contacts[0].Name)
+
+	count, err := repo.Count(context.Background(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, 21, count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
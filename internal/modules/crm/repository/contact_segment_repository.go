@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/google/uuid"
 )
 
@@ -171,8 +172,8 @@ func (r *contactSegmentRepository) ListSegments(ctx context.Context, filter type
 	}
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argPos)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", argPos)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argPos++
 	}
 
@@ -220,8 +221,8 @@ func (r *contactSegmentRepository) CountSegments(ctx context.Context, filter typ
 	}
 
 	if filter.Name != nil {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argPos)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", argPos)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	var count int
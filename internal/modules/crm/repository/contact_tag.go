@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -59,8 +60,8 @@ func (r *contactTagRepository) FindAll(ctx context.Context, filter types.Contact
 	args = append(args, filter.OrganizationID)
 
 	if filter.Name != nil {
-		query += " AND name LIKE $2"
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", 2)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	query += " ORDER BY name"
@@ -146,8 +147,8 @@ func (r *contactTagRepository) Count(ctx context.Context, filter types.ContactTa
 	args = append(args, filter.OrganizationID)
 
 	if filter.Name != nil {
-		query += " AND name LIKE $2"
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", 2)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	var count int
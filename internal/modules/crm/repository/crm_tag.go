@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type crmTagRepository struct {
+	db *sql.DB
+}
+
+func NewCRMTagRepository(db *sql.DB) types.CRMTagRepository {
+	return &crmTagRepository{db: db}
+}
+
+// crmTagColumns selects a tag from t with the live leads and contacts
+// carrying it. The containment tests use the GIN indexes on the arrays.
+const crmTagColumns = `t.id, t.organization_id, t.name, t.color,
+	(SELECT COUNT(*) FROM leads l
+	 WHERE l.organization_id = t.organization_id AND l.deleted_at IS NULL AND l.tag_ids @> ARRAY[t.id]),
+	(SELECT COUNT(*) FROM contacts c
+	 WHERE c.organization_id = t.organization_id AND c.deleted_at IS NULL AND c.tags @> ARRAY[t.id]),
+	t.created_at, t.updated_at`
+
+// crmTagTargets are the table and tag array column of each taggable record
+var crmTagTargets = map[types.CRMTagTarget]struct{ table, column string }{
+	types.CRMTagTargetLeads:    {"leads", "tag_ids"},
+	types.CRMTagTargetContacts: {"contacts", "tags"},
+}
+
+func scanCRMTag(row rowScanner) (*types.CRMTag, error) {
+	var tag types.CRMTag
+	err := row.Scan(&tag.ID, &tag.OrganizationID, &tag.Name, &tag.Color,
+		&tag.LeadCount, &tag.ContactCount, &tag.CreatedAt, &tag.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func crmTagError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return types.ErrCRMTagExists
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ErrCRMTagNotFound
+	}
+	return err
+}
+
+func (r *crmTagRepository) Create(ctx context.Context, tag types.CRMTag) (*types.CRMTag, error) {
+	created, err := scanCRMTag(r.db.QueryRowContext(ctx, `
+		INSERT INTO crm_tags (organization_id, name, color)
+		VALUES ($1, $2, $3)
+		RETURNING id, organization_id, name, color, 0, 0, created_at, updated_at`,
+		tag.OrganizationID, tag.Name, tag.Color,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", crmTagError(err))
+	}
+	return created, nil
+}
+
+func (r *crmTagRepository) FindByID(ctx context.Context, orgID, id uuid.UUID) (*types.CRMTag, error) {
+	tag, err := scanCRMTag(r.db.QueryRowContext(ctx, `
+		SELECT `+crmTagColumns+`
+		FROM crm_tags t
+		WHERE t.id = $1 AND t.organization_id = $2`,
+		id, orgID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tag: %w", crmTagError(err))
+	}
+	return tag, nil
+}
+
+func (r *crmTagRepository) FindAll(ctx context.Context, filter types.CRMTagFilter) ([]types.CRMTag, error) {
+	query := `
+		SELECT ` + crmTagColumns + `
+		FROM crm_tags t
+		WHERE t.organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+	if filter.Name != nil && *filter.Name != "" {
+		args = append(args, database.LikePattern(*filter.Name, database.MatchModeContains))
+		query += " AND " + database.ILike("t.name", len(args))
+	}
+	query += ` ORDER BY lower(t.name), t.id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []types.CRMTag{}
+	for rows.Next() {
+		tag, err := scanCRMTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, *tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *crmTagRepository) Update(ctx context.Context, tag types.CRMTag) (*types.CRMTag, error) {
+	updated, err := scanCRMTag(r.db.QueryRowContext(ctx, `
+		WITH t AS (
+			UPDATE crm_tags
+			SET name = $3, color = $4, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND organization_id = $2
+			RETURNING *
+		)
+		SELECT `+crmTagColumns+`
+		FROM t`,
+		tag.ID, tag.OrganizationID, tag.Name, tag.Color,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", crmTagError(err))
+	}
+	return updated, nil
+}
+
+func (r *crmTagRepository) Delete(ctx context.Context, orgID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM crm_tags WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	} else if n == 0 {
+		return types.ErrCRMTagNotFound
+	}
+
+	for _, target := range []types.CRMTagTarget{types.CRMTagTargetLeads, types.CRMTagTargetContacts} {
+		t := crmTagTargets[target]
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %[1]s SET %[2]s = array_remove(%[2]s, $2)
+			WHERE organization_id = $1 AND %[2]s @> ARRAY[$2::uuid]`, t.table, t.column),
+			orgID, id,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to remove tag from %s: %w", t.table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *crmTagRepository) MissingIDs(ctx context.Context, orgID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT requested.id
+		FROM unnest($2::uuid[]) AS requested(id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM crm_tags t WHERE t.organization_id = $1 AND t.id = requested.id
+		)`,
+		orgID, pq.Array(ids),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tags: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tag id: %w", err)
+		}
+		missing = append(missing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag ids: %w", err)
+	}
+	return missing, nil
+}
+
+func (r *crmTagRepository) AddToRecord(ctx context.Context, orgID uuid.UUID, target types.CRMTagTarget, recordID uuid.UUID, tagIDs []uuid.UUID) ([]uuid.UUID, error) {
+	t, ok := crmTagTargets[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown target %q", types.ErrInvalidCRMTag, target)
+	}
+	// Tags already carried are skipped so the array never holds duplicates
+	return r.updateRecordTags(ctx, t.table, fmt.Sprintf(`
+		UPDATE %[1]s
+		SET %[2]s = COALESCE(%[2]s, '{}') || ARRAY(
+				SELECT unnest($3::uuid[]) EXCEPT SELECT unnest(COALESCE(%[2]s, '{}'))
+			),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND organization_id = $1 AND deleted_at IS NULL
+		RETURNING %[2]s`, t.table, t.column),
+		orgID, recordID, pq.Array(tagIDs),
+	)
+}
+
+func (r *crmTagRepository) RemoveFromRecord(ctx context.Context, orgID uuid.UUID, target types.CRMTagTarget, recordID, tagID uuid.UUID) ([]uuid.UUID, error) {
+	t, ok := crmTagTargets[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown target %q", types.ErrInvalidCRMTag, target)
+	}
+	return r.updateRecordTags(ctx, t.table, fmt.Sprintf(`
+		UPDATE %[1]s
+		SET %[2]s = array_remove(%[2]s, $3), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND organization_id = $1 AND deleted_at IS NULL
+		RETURNING %[2]s`, t.table, t.column),
+		orgID, recordID, tagID,
+	)
+}
+
+func (r *crmTagRepository) updateRecordTags(ctx context.Context, table, query string, args ...interface{}) ([]uuid.UUID, error) {
+	var tagIDs []uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(pq.Array(&tagIDs))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrTaggedRecordNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s tags: %w", table, err)
+	}
+	if tagIDs == nil {
+		tagIDs = []uuid.UUID{}
+	}
+	return tagIDs, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestCRMTagFindAllCountsUsage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, tagID := uuid.New(), uuid.New()
+	now := time.Now()
+	name := "50%"
+	mock.ExpectQuery(`l\.tag_ids @> ARRAY\[t\.id\].*c\.tags @> ARRAY\[t\.id\].*`+
+		`WHERE t\.organization_id = \$1 AND t\.name ILIKE \$2 .* ORDER BY lower\(t\.name\), t\.id LIMIT \$3`).
+		WithArgs(orgID, `%50\%%`, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "name", "color", "lead_count", "contact_count", "created_at", "updated_at"}).
+			AddRow(tagID, orgID, "50% off", 3, 7, 2, now, now))
+
+	tags, err := NewCRMTagRepository(db).FindAll(context.Background(), types.CRMTagFilter{OrganizationID: orgID, Name: &name, Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, 7, tags[0].LeadCount)
+	assert.Equal(t, 2, tags[0].ContactCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCRMTagDeleteStripsLeadsAndContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, tagID := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM crm_tags WHERE id = \$1 AND organization_id = \$2`).
+		WithArgs(tagID, orgID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE leads SET tag_ids = array_remove\(tag_ids, \$2\)`).
+		WithArgs(orgID, tagID).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`UPDATE contacts SET tags = array_remove\(tags, \$2\)`).
+		WithArgs(orgID, tagID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, NewCRMTagRepository(db).Delete(context.Background(), orgID, tagID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCRMTagDeleteUnknownTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM crm_tags`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = NewCRMTagRepository(db).Delete(context.Background(), uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, types.ErrCRMTagNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCRMTagAddToRecord(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, contactID, kept, added := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectQuery(`UPDATE contacts\s+SET tags = COALESCE\(tags, '\{\}'\) \|\| ARRAY\(\s+`+
+		`SELECT unnest\(\$3::uuid\[\]\) EXCEPT SELECT unnest\(COALESCE\(tags, '\{\}'\)\)`).
+		WithArgs(orgID, contactID, pq.Array([]uuid.UUID{kept, added})).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{" + kept.String() + "," + added.String() + "}"))
+
+	tagIDs, err := NewCRMTagRepository(db).AddToRecord(context.Background(), orgID, types.CRMTagTargetContacts, contactID, []uuid.UUID{kept, added})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{kept, added}, tagIDs)
+
+	// A lead that is not in the organization is not found
+	mock.ExpectQuery(`UPDATE leads\s+SET tag_ids = array_remove\(tag_ids, \$3\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"tag_ids"}))
+	_, err = NewCRMTagRepository(db).RemoveFromRecord(context.Background(), orgID, types.CRMTagTargetLeads, uuid.New(), kept)
+	assert.ErrorIs(t, err, types.ErrTaggedRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
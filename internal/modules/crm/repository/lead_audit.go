@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type leadAuditRepository struct {
+	db *sql.DB
+}
+
+func NewLeadAuditRepository(db *sql.DB) types.LeadAuditRepository {
+	return &leadAuditRepository{db: db}
+}
+
+const leadAuditColumns = `id, organization_id, lead_id, field, old_value, new_value, source, changed_by, changed_at`
+
+func scanLeadAuditEntry(row rowScanner) (*types.LeadAuditEntry, error) {
+	var entry types.LeadAuditEntry
+	var oldValue, newValue []byte
+	err := row.Scan(&entry.ID, &entry.OrganizationID, &entry.LeadID, &entry.Field, &oldValue, &newValue,
+		&entry.Source, &entry.ChangedBy, &entry.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.OldValue = oldValue
+	entry.NewValue = newValue
+	return &entry, nil
+}
+
+// insertLeadAuditEntries stores audit entries within the caller's transaction
+func insertLeadAuditEntries(ctx context.Context, tx *sql.Tx, entries []types.LeadAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO lead_audit_log (`+leadAuditColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare lead audit entry: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		_, err := stmt.ExecContext(ctx, entry.ID, entry.OrganizationID, entry.LeadID, entry.Field,
+			[]byte(entry.OldValue), []byte(entry.NewValue), entry.Source, entry.ChangedBy, entry.ChangedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record lead audit entry: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *leadAuditRepository) Record(ctx context.Context, entries []types.LeadAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertLeadAuditEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lead audit entries: %w", err)
+	}
+	return nil
+}
+
+func (r *leadAuditRepository) FindByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadAuditEntry, error) {
+	query := `
+		SELECT ` + leadAuditColumns + `
+		FROM lead_audit_log
+		WHERE organization_id = $1 AND lead_id = $2
+		ORDER BY changed_at DESC, field`
+
+	return r.queryEntries(ctx, query, orgID, leadID)
+}
+
+func (r *leadAuditRepository) Export(ctx context.Context, filter types.LeadAuditFilter) ([]types.LeadAuditEntry, error) {
+	where := []string{"organization_id = $1", "changed_at >= $2", "changed_at < $3"}
+	args := []interface{}{filter.OrganizationID, filter.From, filter.To}
+	if filter.LeadID != nil {
+		args = append(args, *filter.LeadID)
+		where = append(where, fmt.Sprintf("lead_id = $%d", len(args)))
+	}
+	if filter.Field != nil {
+		args = append(args, *filter.Field)
+		where = append(where, fmt.Sprintf("field = $%d", len(args)))
+	}
+	if filter.ChangedBy != nil {
+		args = append(args, *filter.ChangedBy)
+		where = append(where, fmt.Sprintf("changed_by = $%d", len(args)))
+	}
+
+	query := `
+		SELECT ` + leadAuditColumns + `
+		FROM lead_audit_log
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY changed_at, lead_id, field`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	return r.queryEntries(ctx, query, args...)
+}
+
+func (r *leadAuditRepository) queryEntries(ctx context.Context, query string, args ...interface{}) ([]types.LeadAuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.LeadAuditEntry{}
+	for rows.Next() {
+		entry, err := scanLeadAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead audit entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestLeadAuditExportFiltersAndLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID, userID := uuid.New(), uuid.New(), uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	field := "priority"
+
+	mock.ExpectQuery(`WHERE organization_id = \$1 AND changed_at >= \$2 AND changed_at < \$3 AND field = \$4 AND changed_by = \$5\s+ORDER BY changed_at, lead_id, field LIMIT 100`).
+		WithArgs(orgID, from, to, field, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "lead_id", "field", "old_value", "new_value", "source", "changed_by", "changed_at"}).
+			AddRow(uuid.NewString(), orgID.String(), leadID.String(), field, []byte(`"low"`), []byte(`"high"`), "bulk_update", userID.String(), from.Add(time.Hour)))
+
+	entries, err := NewLeadAuditRepository(db).Export(context.Background(), types.LeadAuditFilter{
+		OrganizationID: orgID, From: from, To: to, Field: &field, ChangedBy: &userID, Limit: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, leadID, entries[0].LeadID)
+	assert.JSONEq(t, `"high"`, string(entries[0].NewValue))
+	assert.Equal(t, types.LeadAuditBulkUpdate, entries[0].Source)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
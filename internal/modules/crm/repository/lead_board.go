@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+type leadBoardRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewLeadBoardRepository creates a lead board repository rendering SQL for
+// dialect; a nil dialect means PostgreSQL
+func NewLeadBoardRepository(db *sql.DB, dialect database.Dialect) types.LeadBoardRepository {
+	return &leadBoardRepository{db: db, dialect: database.DialectOrDefault(dialect)}
+}
+
+// Totals counts the filtered leads of each stage and sums their expected
+// revenue, in one pass over the leads
+func (r *leadBoardRepository) Totals(ctx context.Context, filter types.LeadFilter) ([]types.LeadBoardTotals, error) {
+	compiled := compileLeadFilter(filter, r.dialect)
+	query, args := compiled.Bind(`
+		SELECT stage_id, COUNT(*), COALESCE(SUM(expected_revenue), 0)
+		FROM leads
+		WHERE ` + compiled.Where + `
+		GROUP BY stage_id`)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total lead board: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []types.LeadBoardTotals{}
+	for rows.Next() {
+		var total types.LeadBoardTotals
+		if err := rows.Scan(&total.StageID, &total.Count, &total.ExpectedRevenue); err != nil {
+			return nil, fmt.Errorf("failed to scan lead board totals: %w", err)
+		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead board totals: %w", err)
+	}
+	return totals, nil
+}
+
+// Cards ranks the filtered leads within their stage and returns each
+// stage's ranks past its offset, so every column is read in one query
+func (r *leadBoardRepository) Cards(ctx context.Context, filter types.LeadFilter, limit int, offsets map[uuid.UUID]int) ([]*types.Lead, error) {
+	compiled := compileLeadFilter(filter, r.dialect)
+	offset := boardOffsetSQL(offsets, &compiled.Args)
+	query, args := compiled.Bind(`
+		SELECT ` + leadListColumns + `
+		FROM (
+			SELECT ` + leadListColumns + `,
+				ROW_NUMBER() OVER (PARTITION BY stage_id ORDER BY ` + compiled.OrderBy + `) AS board_rank
+			FROM leads
+			WHERE ` + compiled.Where + `
+		) board
+		WHERE board_rank > ` + offset + ` AND board_rank <= ` + offset + fmt.Sprintf(" + %d", limit) + `
+		ORDER BY board_rank`)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lead board cards: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []*types.Lead{}
+	for rows.Next() {
+		var lead types.Lead
+		if err := rows.Scan(leadListDest(&lead)...); err != nil {
+			return nil, fmt.Errorf("failed to scan lead: %w", err)
+		}
+		leads = append(leads, &lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during lead iteration: %w", err)
+	}
+	return leads, nil
+}
+
+// boardOffsetSQL returns the expression giving the offset of a lead's
+// stage, appending the stage IDs it compares to args. Stages are ordered so
+// the SQL and its arguments are deterministic.
+func boardOffsetSQL(offsets map[uuid.UUID]int, args *[]interface{}) string {
+	stageIDs := make([]uuid.UUID, 0, len(offsets))
+	for stageID, offset := range offsets {
+		if offset > 0 {
+			stageIDs = append(stageIDs, stageID)
+		}
+	}
+	if len(stageIDs) == 0 {
+		return "0"
+	}
+	sort.Slice(stageIDs, func(i, j int) bool { return stageIDs[i].String() < stageIDs[j].String() })
+
+	var expr strings.Builder
+	expr.WriteString("(CASE")
+	for _, stageID := range stageIDs {
+		if stageID == uuid.Nil {
+			fmt.Fprintf(&expr, " WHEN stage_id IS NULL THEN %d", offsets[stageID])
+			continue
+		}
+		*args = append(*args, stageID)
+		fmt.Fprintf(&expr, " WHEN stage_id = $%d THEN %d", len(*args), offsets[stageID])
+	}
+	expr.WriteString(" ELSE 0 END)")
+	return expr.String()
+}
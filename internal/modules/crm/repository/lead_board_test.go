@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+func TestLeadBoardCardsSkipEachStageOffset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, stageID := uuid.New(), uuid.New()
+	filter := types.LeadFilter{OrganizationID: orgID}
+
+	mock.ExpectQuery(`ROW_NUMBER\(\) OVER \(PARTITION BY stage_id ORDER BY name ASC\).*`+
+		`WHERE board_rank > \(CASE WHEN stage_id IS NULL THEN 5 WHEN stage_id = \$2 THEN 20 ELSE 0 END\) `+
+		`AND board_rank <= \(CASE .* ELSE 0 END\) \+ 20`).
+		WithArgs(orgID, stageID).
+		WillReturnRows(sqlmock.NewRows(nil))
+
+	leads, err := NewLeadBoardRepository(db, database.Postgres).Cards(context.Background(), filter, 20,
+		map[uuid.UUID]int{stageID: 20, uuid.Nil: 5, uuid.New(): 0})
+	require.NoError(t, err)
+	assert.Empty(t, leads)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeadBoardTotalsByStage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, stageID := uuid.New(), uuid.New()
+	mock.ExpectQuery(`SELECT stage_id, COUNT\(\*\), COALESCE\(SUM\(expected_revenue\), 0\)\s+FROM leads\s+WHERE .*GROUP BY stage_id`).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"stage_id", "count", "sum"}).
+			AddRow(stageID.String(), 3, 300.5).
+			AddRow(nil, 1, 0))
+
+	totals, err := NewLeadBoardRepository(db, database.Postgres).Totals(context.Background(), types.LeadFilter{OrganizationID: orgID})
+	require.NoError(t, err)
+	assert.Equal(t, []types.LeadBoardTotals{
+		{StageID: &stageID, Count: 3, ExpectedRevenue: 300.5},
+		{Count: 1},
+	}, totals)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
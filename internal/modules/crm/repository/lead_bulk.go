@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadBulkRepository struct {
+	db *sql.DB
+}
+
+func NewLeadBulkRepository(db *sql.DB) types.LeadBulkRepository {
+	return &leadBulkRepository{db: db}
+}
+
+func (r *leadBulkRepository) FindTargets(ctx context.Context, filter types.LeadFilter, ids []uuid.UUID, limit int) ([]types.LeadBulkTarget, error) {
+	var query string
+	var args []interface{}
+	if len(ids) > 0 {
+		query = `
+			SELECT l.id, l.team_id, l.stage_id
+			FROM unnest($2::uuid[]) WITH ORDINALITY AS t(id, n)
+			JOIN leads l ON l.id = t.id
+			WHERE l.organization_id = $1 AND l.deleted_at IS NULL
+			ORDER BY t.n`
+		args = []interface{}{filter.OrganizationID, pq.Array(ids)}
+	} else {
+		// Bulk queries rely on unnest and row locks, so they only run on
+		// PostgreSQL and CockroachDB, which share its filter syntax
+		compiled := compileLeadFilter(filter, database.Postgres)
+		query = "SELECT id, team_id, stage_id FROM leads WHERE " + compiled.Where + " ORDER BY name ASC, id"
+		if limit > 0 {
+			query += fmt.Sprintf(" LIMIT %d", limit)
+		}
+		args = compiled.Args
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bulk leads: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []types.LeadBulkTarget{}
+	for rows.Next() {
+		var target types.LeadBulkTarget
+		if err := rows.Scan(&target.ID, &target.TeamID, &target.StageID); err != nil {
+			return nil, fmt.Errorf("failed to scan bulk lead: %w", err)
+		}
+		targets = append(targets, target)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bulk leads: %w", err)
+	}
+
+	return targets, nil
+}
+
+// leadBulkFields are the lead fields a bulk update can change, named as on
+// the lead so their audit entries match those of single updates
+type leadBulkFields struct {
+	StageID     *uuid.UUID         `json:"stage_id"`
+	Probability int                `json:"probability"`
+	AssignedTo  *uuid.UUID         `json:"assigned_to"`
+	Priority    types.LeadPriority `json:"priority"`
+	TagIDs      []uuid.UUID        `json:"tag_ids"`
+}
+
+// Update locks the batch's leads, fails those deleted or moved since they
+// were checked, then applies the changes to the rest with one statement and
+// records their stage moves and changed fields
+func (r *leadBulkRepository) Update(ctx context.Context, batch types.LeadBulkBatch) ([]types.LeadBulkItemResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type lockedLead struct {
+		teamID  *uuid.UUID
+		stageID *uuid.UUID
+		since   time.Time
+		fields  leadBulkFields
+	}
+	locked := make(map[uuid.UUID]lockedLead, len(batch.LeadIDs))
+
+	// Locking in ID order keeps concurrent bulk updates from deadlocking
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, team_id, stage_id, COALESCE(date_last_stage_update, created_at),
+			probability, assigned_to, priority, tag_ids
+		FROM leads
+		WHERE organization_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE`,
+		batch.OrganizationID, pq.Array(batch.LeadIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock bulk leads: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var lead lockedLead
+		err := rows.Scan(&id, &lead.teamID, &lead.stageID, &lead.since,
+			&lead.fields.Probability, &lead.fields.AssignedTo, &lead.fields.Priority, pq.Array(&lead.fields.TagIDs))
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan bulk lead: %w", err)
+		}
+		locked[id] = lead
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating bulk leads: %w", err)
+	}
+	rows.Close()
+
+	moving := batch.Changes.StageID != nil
+	results := make([]types.LeadBulkItemResult, len(batch.LeadIDs))
+	var updateIDs []uuid.UUID
+	failed := false
+	for i, id := range batch.LeadIDs {
+		results[i] = types.LeadBulkItemResult{LeadID: id, Status: types.LeadBulkStatusUpdated}
+		lead, ok := locked[id]
+		switch {
+		case !ok:
+			results[i].Status = types.LeadBulkStatusFailed
+			results[i].Error = "lead not found"
+		case moving && !sameStage(lead.stageID, batch.FromStageIDs[id]):
+			results[i].Status = types.LeadBulkStatusFailed
+			results[i].Error = types.ErrLeadStageChanged.Error()
+		default:
+			updateIDs = append(updateIDs, id)
+			continue
+		}
+		failed = true
+	}
+
+	if failed && batch.AllOrNothing {
+		for i := range results {
+			if results[i].Status == types.LeadBulkStatusUpdated {
+				results[i].Status = types.LeadBulkStatusSkipped
+			}
+		}
+		return results, nil
+	}
+	if len(updateIDs) == 0 {
+		return results, nil
+	}
+
+	set := []string{"updated_at = $3", "updated_by = $4"}
+	args := []interface{}{batch.OrganizationID, pq.Array(updateIDs), batch.ChangedAt, batch.ChangedBy}
+	addSet := func(format string, value interface{}) {
+		args = append(args, value)
+		set = append(set, fmt.Sprintf(format, len(args)))
+	}
+	if moving {
+		addSet("stage_id = $%d", *batch.Changes.StageID)
+		args = append(args, batch.Probability)
+		n := len(args)
+		set = append(set,
+			fmt.Sprintf("system_probability = COALESCE($%d, system_probability)", n),
+			fmt.Sprintf("probability = CASE WHEN probability_overridden THEN probability ELSE COALESCE($%d, probability) END", n))
+		set = append(set, "date_last_stage_update = $3")
+	}
+	if batch.Changes.AssignedTo != nil {
+		addSet("assigned_to = $%d", *batch.Changes.AssignedTo)
+	}
+	if batch.Changes.Priority != nil {
+		addSet("priority = $%d", *batch.Changes.Priority)
+	}
+	if len(batch.Changes.AddTagIDs) > 0 || len(batch.Changes.RemoveTagIDs) > 0 {
+		args = append(args, pq.Array(batch.Changes.AddTagIDs), pq.Array(batch.Changes.RemoveTagIDs))
+		// Keeps the existing tags in order, appends the new ones and drops
+		// the removed ones
+		set = append(set, fmt.Sprintf(`tag_ids = ARRAY(
+			SELECT t.tag_id
+			FROM unnest(COALESCE(tag_ids, '{}') || $%d::uuid[]) WITH ORDINALITY AS t(tag_id, n)
+			WHERE t.tag_id <> ALL($%d::uuid[])
+			GROUP BY t.tag_id
+			ORDER BY MIN(t.n))`, len(args)-1, len(args)))
+	}
+
+	set = append(set, "version = version + 1")
+
+	rows, err = tx.QueryContext(ctx, `
+		UPDATE leads SET `+strings.Join(set, ", ")+`
+		WHERE organization_id = $1 AND id = ANY($2)
+		RETURNING id, stage_id, probability, assigned_to, priority, tag_ids`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update bulk leads: %w", err)
+	}
+	var entries []types.LeadAuditEntry
+	for rows.Next() {
+		var id uuid.UUID
+		var after leadBulkFields
+		err := rows.Scan(&id, &after.StageID, &after.Probability, &after.AssignedTo, &after.Priority, pq.Array(&after.TagIDs))
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan updated lead: %w", err)
+		}
+		changes := audit.Diff(locked[id].fields, after)
+		leadEntries, err := types.NewLeadAuditEntries(batch.OrganizationID, id, changes, types.LeadAuditBulkUpdate, batch.ChangedBy, batch.ChangedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, leadEntries...)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating updated leads: %w", err)
+	}
+	rows.Close()
+
+	if moving {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO lead_stage_history (
+				id, organization_id, lead_id, team_id, from_stage_id, to_stage_id,
+				changed_by, changed_at, duration_seconds
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare lead stage change: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, id := range updateIDs {
+			lead := locked[id]
+			var duration int64
+			if batch.ChangedAt.After(lead.since) {
+				duration = int64(batch.ChangedAt.Sub(lead.since).Seconds())
+			}
+			_, err := stmt.ExecContext(ctx, uuid.New(), batch.OrganizationID, id, lead.teamID, lead.stageID,
+				batch.Changes.StageID, batch.ChangedBy, batch.ChangedAt, duration)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record lead stage change: %w", err)
+			}
+		}
+	}
+
+	if err := insertLeadAuditEntries(ctx, tx, entries); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bulk lead update: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *leadBulkRepository) Delete(ctx context.Context, orgID uuid.UUID, ids []uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE leads SET deleted_at = $3, updated_at = $3, updated_by = $4, version = version + 1
+		WHERE organization_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		RETURNING id`,
+		orgID, pq.Array(ids), deletedAt, deletedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete bulk leads: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted lead: %w", err)
+		}
+		deleted = append(deleted, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted leads: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func sameStage(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
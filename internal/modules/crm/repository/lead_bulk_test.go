@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var lockColumns = []string{"id", "team_id", "stage_id", "since", "probability", "assigned_to", "priority", "tag_ids"}
+
+func TestBulkUpdateMovesLeadsAndFailsMovedOnes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	orgID, userID := uuid.New(), uuid.New()
+	qualified, proposal, other := uuid.New(), uuid.New(), uuid.New()
+	moved, stale, missing := uuid.New(), uuid.New(), uuid.New()
+	priority := types.LeadPriorityHigh
+	probability := 60
+
+	batch := types.LeadBulkBatch{
+		OrganizationID: orgID,
+		LeadIDs:        []uuid.UUID{moved, stale, missing},
+		Changes:        types.LeadBulkChanges{StageID: &proposal, Priority: &priority},
+		FromStageIDs:   map[uuid.UUID]*uuid.UUID{moved: &qualified, stale: &qualified, missing: nil},
+		Probability:    &probability,
+		ChangedBy:      &userID,
+		ChangedAt:      now,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`ORDER BY id\s+FOR UPDATE`).
+		WithArgs(orgID, pq.Array(batch.LeadIDs)).
+		WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(moved.String(), nil, qualified.String(), now.Add(-time.Hour), 20, nil, "medium", "{}").
+			AddRow(stale.String(), nil, other.String(), now.Add(-time.Hour), 20, nil, "medium", "{}"))
+	// Overridden leads keep their probability
+	mock.ExpectQuery(`UPDATE leads SET updated_at = \$3, updated_by = \$4, stage_id = \$5, `+
+		`system_probability = COALESCE\(\$6, system_probability\), `+
+		`probability = CASE WHEN probability_overridden THEN probability ELSE COALESCE\(\$6, probability\) END, `+
+		`date_last_stage_update = \$3, priority = \$7`).
+		WithArgs(orgID, pq.Array([]uuid.UUID{moved}), now, &userID, proposal, 60, priority).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stage_id", "probability", "assigned_to", "priority", "tag_ids"}).
+			AddRow(moved.String(), proposal.String(), 60, nil, "high", "{}"))
+	mock.ExpectPrepare("INSERT INTO lead_stage_history").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), orgID, moved, nil, &qualified, &proposal, &userID, now, int64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The changed fields are audited in declaration order
+	auditStmt := mock.ExpectPrepare("INSERT INTO lead_audit_log")
+	for _, change := range [][2]string{
+		{"stage_id", `"` + proposal.String() + `"`},
+		{"probability", "60"},
+		{"priority", `"high"`},
+	} {
+		auditStmt.ExpectExec().
+			WithArgs(sqlmock.AnyArg(), orgID, moved, change[0], sqlmock.AnyArg(), []byte(change[1]),
+				types.LeadAuditBulkUpdate, &userID, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	results, err := NewLeadBulkRepository(db).Update(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, types.LeadBulkStatusUpdated, results[0].Status)
+	assert.Equal(t, types.LeadBulkStatusFailed, results[1].Status)
+	assert.Equal(t, types.ErrLeadStageChanged.Error(), results[1].Error)
+	assert.Equal(t, types.LeadBulkStatusFailed, results[2].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBulkUpdateAllOrNothingRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, owner := uuid.New(), uuid.New()
+	live, missing := uuid.New(), uuid.New()
+	batch := types.LeadBulkBatch{
+		OrganizationID: orgID,
+		LeadIDs:        []uuid.UUID{live, missing},
+		Changes:        types.LeadBulkChanges{AssignedTo: &owner},
+		AllOrNothing:   true,
+		ChangedAt:      time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(lockColumns).
+			AddRow(live.String(), nil, nil, time.Now(), 10, nil, "medium", nil))
+	mock.ExpectRollback()
+
+	results, err := NewLeadBulkRepository(db).Update(context.Background(), batch)
+	require.NoError(t, err)
+	assert.Equal(t, types.LeadBulkStatusSkipped, results[0].Status)
+	assert.Equal(t, types.LeadBulkStatusFailed, results[1].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBulkFindTargetsByIDsKeepsRequestOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, first, second := uuid.New(), uuid.New(), uuid.New()
+	ids := []uuid.UUID{first, second}
+
+	mock.ExpectQuery(`unnest\(\$2::uuid\[\]\) WITH ORDINALITY`).
+		WithArgs(orgID, pq.Array(ids)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "stage_id"}).
+			AddRow(first.String(), nil, nil).
+			AddRow(second.String(), nil, nil))
+
+	targets, err := NewLeadBulkRepository(db).FindTargets(context.Background(), types.LeadFilter{OrganizationID: orgID}, ids, 0)
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	assert.Equal(t, first, targets[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,648 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadCadenceRepository struct {
+	db *sql.DB
+}
+
+func NewLeadCadenceRepository(db *sql.DB) types.LeadCadenceRepository {
+	return &leadCadenceRepository{db: db}
+}
+
+const leadCadenceColumns = `id, organization_id, name, source_id, stage_id, active,
+	created_at, updated_at, created_by, updated_by`
+
+const leadCadenceEnrollmentColumns = `id, organization_id, lead_id, cadence_id, cadence_name, source_id,
+	stage_id, assigned_to, entered_at, status, finished_at`
+
+const leadCadenceTaskColumns = `t.id, t.enrollment_id, t.sequence, t.activity_id, t.activity_type, t.summary,
+	t.due_at, t.status, t.completed_at, t.skipped_by`
+
+// stopLeadCadenceEnrollments stops the active enrollments e matching the
+// condition, skips their pending tasks and cancels the activities of those
+// still planned, returning the number stopped. $1 is the time of the stop.
+const stopLeadCadenceEnrollments = `
+	WITH stopped AS (
+		UPDATE lead_cadence_enrollments e SET status = 'stopped', finished_at = $1
+		%s
+		RETURNING e.id
+	), skipped AS (
+		UPDATE lead_cadence_tasks t SET status = 'skipped', completed_at = $1
+		FROM stopped s
+		WHERE t.enrollment_id = s.id AND t.status = 'pending'
+		RETURNING t.activity_id
+	), cancelled AS (
+		UPDATE activities a SET state = 'cancelled', updated_at = $1
+		FROM skipped k
+		WHERE a.id = k.activity_id AND a.state = 'planned'
+		RETURNING a.id
+	)
+	SELECT COUNT(*) FROM stopped`
+
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func scanLeadCadence(row rowScanner) (*types.LeadCadence, error) {
+	var cadence types.LeadCadence
+	err := row.Scan(&cadence.ID, &cadence.OrganizationID, &cadence.Name, &cadence.SourceID, &cadence.StageID,
+		&cadence.Active, &cadence.CreatedAt, &cadence.UpdatedAt, &cadence.CreatedBy, &cadence.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	cadence.Steps = []types.LeadCadenceStep{}
+	return &cadence, nil
+}
+
+func scanLeadCadenceEnrollment(row rowScanner) (*types.LeadCadenceEnrollment, error) {
+	var enrollment types.LeadCadenceEnrollment
+	err := row.Scan(&enrollment.ID, &enrollment.OrganizationID, &enrollment.LeadID, &enrollment.CadenceID,
+		&enrollment.CadenceName, &enrollment.SourceID, &enrollment.StageID, &enrollment.AssignedTo,
+		&enrollment.EnteredAt, &enrollment.Status, &enrollment.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	enrollment.Tasks = []types.LeadCadenceTask{}
+	return &enrollment, nil
+}
+
+func scanLeadCadenceTask(row rowScanner) (*types.LeadCadenceTask, error) {
+	var task types.LeadCadenceTask
+	err := row.Scan(&task.ID, &task.EnrollmentID, &task.Sequence, &task.ActivityID, &task.ActivityType,
+		&task.Summary, &task.DueAt, &task.Status, &task.CompletedAt, &task.SkippedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func leadCadenceError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return types.ErrLeadCadenceExists
+	}
+	return err
+}
+
+func (r *leadCadenceRepository) CreateCadence(ctx context.Context, cadence types.LeadCadence) (*types.LeadCadence, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO lead_cadences (
+			id, organization_id, name, source_id, stage_id, active,
+			created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + leadCadenceColumns
+
+	created, err := scanLeadCadence(tx.QueryRowContext(ctx, query,
+		cadence.ID, cadence.OrganizationID, cadence.Name, cadence.SourceID, cadence.StageID, cadence.Active,
+		cadence.CreatedAt, cadence.UpdatedAt, cadence.CreatedBy, cadence.UpdatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead cadence: %w", leadCadenceError(err))
+	}
+	if created.Steps, err = insertLeadCadenceSteps(ctx, tx, created.ID, cadence.Steps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead cadence: %w", err)
+	}
+	return created, nil
+}
+
+func (r *leadCadenceRepository) FindCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.LeadCadence, error) {
+	query := `SELECT ` + leadCadenceColumns + ` FROM lead_cadences WHERE id = $1 AND organization_id = $2`
+
+	cadence, err := scanLeadCadence(r.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead cadence not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to find lead cadence: %w", err)
+	}
+
+	steps, err := r.querySteps(ctx, `s.cadence_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	cadence.Steps = append(cadence.Steps, steps[cadence.ID]...)
+	return cadence, nil
+}
+
+func (r *leadCadenceRepository) ListCadences(ctx context.Context, orgID uuid.UUID) ([]types.LeadCadence, error) {
+	query := `
+		SELECT ` + leadCadenceColumns + `
+		FROM lead_cadences
+		WHERE organization_id = $1
+		ORDER BY stage_id IS NULL, source_id IS NULL, name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead cadences: %w", err)
+	}
+	defer rows.Close()
+
+	cadences := []types.LeadCadence{}
+	for rows.Next() {
+		cadence, err := scanLeadCadence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence: %w", err)
+		}
+		cadences = append(cadences, *cadence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadences: %w", err)
+	}
+	if len(cadences) == 0 {
+		return cadences, nil
+	}
+
+	steps, err := r.querySteps(ctx, `c.organization_id = $1`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cadences {
+		cadences[i].Steps = append(cadences[i].Steps, steps[cadences[i].ID]...)
+	}
+	return cadences, nil
+}
+
+// UpdateCadence replaces the cadence and its steps. Enrollments already
+// created keep the tasks they started with.
+func (r *leadCadenceRepository) UpdateCadence(ctx context.Context, cadence types.LeadCadence) (*types.LeadCadence, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE lead_cadences SET
+			name = $1, source_id = $2, stage_id = $3, active = $4, updated_at = $5, updated_by = $6
+		WHERE id = $7 AND organization_id = $8
+		RETURNING ` + leadCadenceColumns
+
+	updated, err := scanLeadCadence(tx.QueryRowContext(ctx, query,
+		cadence.Name, cadence.SourceID, cadence.StageID, cadence.Active, cadence.UpdatedAt, cadence.UpdatedBy,
+		cadence.ID, cadence.OrganizationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead cadence not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to update lead cadence: %w", leadCadenceError(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lead_cadence_steps WHERE cadence_id = $1`, updated.ID); err != nil {
+		return nil, fmt.Errorf("failed to replace lead cadence steps: %w", err)
+	}
+	if updated.Steps, err = insertLeadCadenceSteps(ctx, tx, updated.ID, cadence.Steps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead cadence update: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteCadence removes the cadence and stops its active enrollments. The
+// enrollments are kept for reporting.
+func (r *leadCadenceRepository) DeleteCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := stopEnrollments(ctx, tx,
+		`WHERE e.cadence_id = $2 AND e.organization_id = $3 AND e.status = 'active'`, at, id, orgID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM lead_cadences WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lead cadence: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("lead cadence not found: %w", sql.ErrNoRows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lead cadence deletion: %w", err)
+	}
+	return nil
+}
+
+// UnenrolledEntries matches each open lead with the most specific active
+// cadence, a stage match ranking above a source match. A cadence with a
+// stage is entered with the lead's current stage entry, any other with the
+// lead's creation. Entries that happened before the cadence was created are
+// not enrolled, so a new cadence does not flood the backlog with tasks.
+func (r *leadCadenceRepository) UnenrolledEntries(ctx context.Context, limit int) ([]types.LeadCadenceEntry, error) {
+	query := `
+		SELECT l.id, l.organization_id, c.id, COALESCE(l.assigned_to, l.user_id), e.entered_at
+		FROM leads l
+		CROSS JOIN LATERAL (
+			SELECT c.id, c.stage_id, c.created_at
+			FROM lead_cadences c
+			WHERE c.organization_id = l.organization_id AND c.active
+				AND (c.source_id IS NULL OR c.source_id = l.source_id)
+				AND (c.stage_id IS NULL OR c.stage_id = l.stage_id)
+				AND EXISTS (SELECT 1 FROM lead_cadence_steps s WHERE s.cadence_id = c.id)
+			ORDER BY c.stage_id IS NULL, c.source_id IS NULL
+			LIMIT 1
+		) c
+		CROSS JOIN LATERAL (
+			SELECT CASE WHEN c.stage_id IS NULL THEN l.created_at
+				ELSE COALESCE(l.date_last_stage_update, l.created_at) END AS entered_at
+		) e
+		WHERE l.deleted_at IS NULL AND COALESCE(l.active, true)
+			AND l.status IN ('new', 'in_progress')
+			AND e.entered_at >= c.created_at
+			AND NOT EXISTS (
+				SELECT 1 FROM lead_cadence_enrollments n
+				WHERE n.lead_id = l.id AND n.cadence_id = c.id AND n.entered_at = e.entered_at
+			)
+		ORDER BY e.entered_at
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unenrolled lead cadence entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.LeadCadenceEntry{}
+	for rows.Next() {
+		var entry types.LeadCadenceEntry
+		if err := rows.Scan(&entry.LeadID, &entry.OrganizationID, &entry.CadenceID, &entry.AssignedTo,
+			&entry.EnteredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadence entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Enroll stores the enrollment and creates a planned activity on the lead
+// for each of its tasks, stopping the lead's other active enrollments. It
+// reports false, changing nothing, when the entry is already enrolled.
+func (r *leadCadenceRepository) Enroll(ctx context.Context, enrollment types.LeadCadenceEnrollment) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO lead_cadence_enrollments (
+			id, organization_id, lead_id, cadence_id, cadence_name, source_id, stage_id,
+			assigned_to, entered_at, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'active')
+		ON CONFLICT (lead_id, cadence_id, entered_at) DO NOTHING
+		RETURNING id`,
+		enrollment.ID, enrollment.OrganizationID, enrollment.LeadID, enrollment.CadenceID, enrollment.CadenceName,
+		enrollment.SourceID, enrollment.StageID, enrollment.AssignedTo, enrollment.EnteredAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to enroll lead in cadence: %w", err)
+	}
+
+	if _, err := stopEnrollments(ctx, tx,
+		`WHERE e.lead_id = $2 AND e.id <> $3 AND e.status = 'active'`, enrollment.EnteredAt, enrollment.LeadID, id); err != nil {
+		return false, err
+	}
+
+	for _, task := range enrollment.Tasks {
+		activityID := uuid.New()
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO activities (id, organization_id, activity_type, summary, note, date_deadline, user_id,
+				assigned_to, res_model, res_id, state, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7, 'leads', $8, 'planned', $9, $9)`,
+			activityID, enrollment.OrganizationID, task.ActivityType, task.Summary, task.Note, task.DueAt,
+			enrollment.AssignedTo, enrollment.LeadID, enrollment.EnteredAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to create lead cadence activity: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO lead_cadence_tasks (
+				id, organization_id, enrollment_id, sequence, activity_id, activity_type, summary, due_at, status
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')`,
+			task.ID, enrollment.OrganizationID, id, task.Sequence, activityID, task.ActivityType, task.Summary,
+			task.DueAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to create lead cadence task: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit lead cadence enrollment: %w", err)
+	}
+	return true, nil
+}
+
+// SyncEnrollments closes the pending tasks whose activity was done,
+// cancelled or deleted, completes the active enrollments left without a
+// pending task and then stops those whose lead closed, was deleted or left
+// the enrollment's source or stage entry
+func (r *leadCadenceRepository) SyncEnrollments(ctx context.Context, now time.Time) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE lead_cadence_tasks t SET
+			status = CASE WHEN a.state = 'done' THEN 'done' ELSE 'skipped' END,
+			completed_at = CASE WHEN a.state = 'done' THEN COALESCE(a.done_date, a.updated_at, $1) ELSE $1 END
+		FROM lead_cadence_tasks p
+		LEFT JOIN activities a ON a.id = p.activity_id
+		WHERE t.id = p.id AND t.status = 'pending'
+			AND (a.id IS NULL OR a.state IN ('done', 'cancelled'))`,
+		now,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sync lead cadence tasks: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lead_cadence_enrollments e SET
+			status = 'completed',
+			finished_at = COALESCE((SELECT MAX(t.completed_at) FROM lead_cadence_tasks t WHERE t.enrollment_id = e.id), $1)
+		WHERE e.status = 'active'
+			AND NOT EXISTS (SELECT 1 FROM lead_cadence_tasks t WHERE t.enrollment_id = e.id AND t.status = 'pending')`,
+		now,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to complete lead cadence enrollments: %w", err)
+	}
+	completed, _ := result.RowsAffected()
+
+	stopped, err := stopEnrollments(ctx, tx, `
+		FROM leads l
+		WHERE l.id = e.lead_id AND e.status = 'active' AND (
+			l.deleted_at IS NOT NULL OR l.status NOT IN ('new', 'in_progress')
+			OR (e.stage_id IS NOT NULL AND (l.stage_id IS DISTINCT FROM e.stage_id
+				OR COALESCE(l.date_last_stage_update, l.created_at) <> e.entered_at))
+			OR (e.source_id IS NOT NULL AND l.source_id IS DISTINCT FROM e.source_id))`, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit lead cadence sync: %w", err)
+	}
+	return int(completed), stopped, nil
+}
+
+// SkipTask skips a pending task and cancels its activity if still planned
+func (r *leadCadenceRepository) SkipTask(ctx context.Context, orgID uuid.UUID, id uuid.UUID, skippedBy *uuid.UUID, at time.Time) (*types.LeadCadenceTask, error) {
+	query := `
+		WITH skipped AS (
+			UPDATE lead_cadence_tasks SET status = 'skipped', completed_at = $1, skipped_by = $2
+			WHERE id = $3 AND organization_id = $4 AND status = 'pending'
+			RETURNING *
+		), cancelled AS (
+			UPDATE activities a SET state = 'cancelled', updated_at = $1
+			FROM skipped s
+			WHERE a.id = s.activity_id AND a.state = 'planned'
+			RETURNING a.id
+		)
+		SELECT ` + leadCadenceTaskColumns + ` FROM skipped t`
+
+	task, err := scanLeadCadenceTask(r.db.QueryRowContext(ctx, query, at, skippedBy, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.closedOrMissing(ctx, "lead_cadence_tasks", "lead cadence task", orgID, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to skip lead cadence task: %w", err)
+	}
+	return task, nil
+}
+
+func (r *leadCadenceRepository) StopEnrollment(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error {
+	stopped, err := stopEnrollments(ctx, r.db,
+		`WHERE e.id = $2 AND e.organization_id = $3 AND e.status = 'active'`, at, id, orgID)
+	if err != nil {
+		return err
+	}
+	if stopped == 0 {
+		return r.closedOrMissing(ctx, "lead_cadence_enrollments", "lead cadence enrollment", orgID, id)
+	}
+	return nil
+}
+
+func (r *leadCadenceRepository) FindEnrollments(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadCadenceEnrollment, error) {
+	query := `
+		SELECT ` + leadCadenceEnrollmentColumns + `
+		FROM lead_cadence_enrollments
+		WHERE lead_id = $1 AND organization_id = $2
+		ORDER BY entered_at DESC, id`
+
+	rows, err := r.db.QueryContext(ctx, query, leadID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead cadence enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	enrollments := []types.LeadCadenceEnrollment{}
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		enrollment, err := scanLeadCadenceEnrollment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence enrollment: %w", err)
+		}
+		index[enrollment.ID] = len(enrollments)
+		enrollments = append(enrollments, *enrollment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadence enrollments: %w", err)
+	}
+	if len(enrollments) == 0 {
+		return enrollments, nil
+	}
+
+	taskQuery := `
+		SELECT ` + leadCadenceTaskColumns + `
+		FROM lead_cadence_tasks t
+		JOIN lead_cadence_enrollments e ON e.id = t.enrollment_id
+		WHERE e.lead_id = $1 AND e.organization_id = $2
+		ORDER BY t.sequence`
+
+	taskRows, err := r.db.QueryContext(ctx, taskQuery, leadID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead cadence tasks: %w", err)
+	}
+	defer taskRows.Close()
+
+	for taskRows.Next() {
+		task, err := scanLeadCadenceTask(taskRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence task: %w", err)
+		}
+		if i, ok := index[task.EnrollmentID]; ok {
+			enrollments[i].Tasks = append(enrollments[i].Tasks, *task)
+		}
+	}
+
+	if err := taskRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadence tasks: %w", err)
+	}
+
+	return enrollments, nil
+}
+
+// Performance counts the enrollments and tasks of each of the
+// organization's cadences
+func (r *leadCadenceRepository) Performance(ctx context.Context, orgID uuid.UUID) ([]types.LeadCadencePerformance, error) {
+	query := `
+		SELECT c.id, c.name,
+			COUNT(e.id),
+			COUNT(e.id) FILTER (WHERE e.status = 'active'),
+			COUNT(e.id) FILTER (WHERE e.status = 'completed'),
+			COUNT(e.id) FILTER (WHERE e.status = 'stopped'),
+			COALESCE(SUM(t.done), 0), COALESCE(SUM(t.skipped), 0), COALESCE(SUM(t.pending), 0)
+		FROM lead_cadences c
+		LEFT JOIN lead_cadence_enrollments e ON e.cadence_id = c.id
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) FILTER (WHERE t.status = 'done') AS done,
+				COUNT(*) FILTER (WHERE t.status = 'skipped') AS skipped,
+				COUNT(*) FILTER (WHERE t.status = 'pending') AS pending
+			FROM lead_cadence_tasks t
+			WHERE t.enrollment_id = e.id
+		) t ON true
+		WHERE c.organization_id = $1
+		GROUP BY c.id, c.name
+		ORDER BY c.name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead cadence performance: %w", err)
+	}
+	defer rows.Close()
+
+	performance := []types.LeadCadencePerformance{}
+	for rows.Next() {
+		var p types.LeadCadencePerformance
+		if err := rows.Scan(&p.CadenceID, &p.Name, &p.Enrolled, &p.Active, &p.Completed, &p.Stopped,
+			&p.TasksDone, &p.TasksSkipped, &p.TasksPending); err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence performance: %w", err)
+		}
+		if finished := p.Completed + p.Stopped; finished > 0 {
+			p.CompletionRate = float64(p.Completed) / float64(finished) * 100
+		}
+		performance = append(performance, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadence performance: %w", err)
+	}
+
+	return performance, nil
+}
+
+func (r *leadCadenceRepository) querySteps(ctx context.Context, where string, args ...interface{}) (map[uuid.UUID][]types.LeadCadenceStep, error) {
+	query := `
+		SELECT s.cadence_id, s.sequence, s.offset_minutes, s.activity_type, s.summary, s.note
+		FROM lead_cadence_steps s
+		JOIN lead_cadences c ON c.id = s.cadence_id
+		WHERE ` + where + `
+		ORDER BY s.cadence_id, s.sequence`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead cadence steps: %w", err)
+	}
+	defer rows.Close()
+
+	steps := make(map[uuid.UUID][]types.LeadCadenceStep)
+	for rows.Next() {
+		var cadenceID uuid.UUID
+		var step types.LeadCadenceStep
+		if err := rows.Scan(&cadenceID, &step.Sequence, &step.OffsetMinutes, &step.ActivityType, &step.Summary,
+			&step.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan lead cadence step: %w", err)
+		}
+		steps[cadenceID] = append(steps[cadenceID], step)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead cadence steps: %w", err)
+	}
+
+	return steps, nil
+}
+
+// closedOrMissing tells a row that is no longer open from one that does
+// not exist in the organization
+func (r *leadCadenceRepository) closedOrMissing(ctx context.Context, table string, what string, orgID uuid.UUID, id uuid.UUID) error {
+	var status string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT status FROM `+table+` WHERE id = $1 AND organization_id = $2`, id, orgID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s not found: %w", what, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", what, err)
+	}
+	return types.ErrLeadCadenceClosed
+}
+
+func insertLeadCadenceSteps(ctx context.Context, tx *sql.Tx, cadenceID uuid.UUID, steps []types.LeadCadenceStep) ([]types.LeadCadenceStep, error) {
+	for _, step := range steps {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO lead_cadence_steps (cadence_id, sequence, offset_minutes, activity_type, summary, note)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			cadenceID, step.Sequence, step.OffsetMinutes, step.ActivityType, step.Summary, step.Note,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lead cadence step: %w", err)
+		}
+	}
+	return append([]types.LeadCadenceStep{}, steps...), nil
+}
+
+func stopEnrollments(ctx context.Context, db execQuerier, where string, at time.Time, args ...interface{}) (int, error) {
+	var stopped int
+	err := db.QueryRowContext(ctx, fmt.Sprintf(stopLeadCadenceEnrollments, where), append([]interface{}{at}, args...)...).
+		Scan(&stopped)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stop lead cadence enrollments: %w", err)
+	}
+	return stopped, nil
+}
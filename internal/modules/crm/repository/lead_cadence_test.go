@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestEnrollCreatesActivitiesAndStopsOtherEnrollments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	entered := time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC)
+	orgID, leadID, cadenceID, assignee := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	enrollment := types.LeadCadenceEnrollment{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		LeadID:         leadID,
+		CadenceID:      &cadenceID,
+		CadenceName:    "Webinar follow-up",
+		AssignedTo:     &assignee,
+		EnteredAt:      entered,
+		Tasks: []types.LeadCadenceTask{
+			{ID: uuid.New(), Sequence: 1, ActivityType: types.ActivityTypeEmail, Summary: "Send recording", DueAt: entered},
+			{ID: uuid.New(), Sequence: 2, ActivityType: types.ActivityTypeCall, Summary: "Call", DueAt: entered.Add(48 * time.Hour)},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`ON CONFLICT \(lead_id, cadence_id, entered_at\) DO NOTHING`).
+		WithArgs(enrollment.ID, orgID, leadID, &cadenceID, "Webinar follow-up", nil, nil, &assignee, entered).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(enrollment.ID.String()))
+	mock.ExpectQuery(`(?s)UPDATE lead_cadence_enrollments e SET status = 'stopped'.*WHERE e.lead_id = \$2 AND e.id <> \$3`).
+		WithArgs(entered, leadID, enrollment.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	for _, task := range enrollment.Tasks {
+		mock.ExpectExec("INSERT INTO activities").
+			WithArgs(sqlmock.AnyArg(), orgID, task.ActivityType, task.Summary, nil, task.DueAt, &assignee, leadID, entered).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO lead_cadence_tasks").
+			WithArgs(task.ID, orgID, enrollment.ID, task.Sequence, sqlmock.AnyArg(), task.ActivityType, task.Summary, task.DueAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	enrolled, err := NewLeadCadenceRepository(db).Enroll(context.Background(), enrollment)
+	require.NoError(t, err)
+	assert.True(t, enrolled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnrollIgnoresEnrolledEntry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO lead_cadence_enrollments").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	enrolled, err := NewLeadCadenceRepository(db).Enroll(context.Background(), types.LeadCadenceEnrollment{
+		ID:        uuid.New(),
+		LeadID:    uuid.New(),
+		EnteredAt: time.Now(),
+		Tasks:     []types.LeadCadenceTask{{ID: uuid.New(), Sequence: 1}},
+	})
+	require.NoError(t, err)
+	assert.False(t, enrolled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncEnrollmentsCompletesBeforeStopping(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`(?s)UPDATE lead_cadence_tasks t SET.*a.state IN \('done', 'cancelled'\)`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE lead_cadence_enrollments e SET\s+status = 'completed'`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`(?s)status = 'stopped'.*FROM leads l.*l.source_id IS DISTINCT FROM e.source_id`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	completed, stopped, err := NewLeadCadenceRepository(db).SyncEnrollments(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, completed)
+	assert.Equal(t, 1, stopped)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSkipTaskReportsClosedTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, taskID := uuid.New(), uuid.New()
+	at := time.Now()
+
+	mock.ExpectQuery(`UPDATE lead_cadence_tasks SET status = 'skipped'`).
+		WithArgs(at, nil, taskID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT status FROM lead_cadence_tasks").
+		WithArgs(taskID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("done"))
+
+	_, err = NewLeadCadenceRepository(db).SkipTask(context.Background(), orgID, taskID, nil, at)
+	assert.ErrorIs(t, err, types.ErrLeadCadenceClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCadencePerformanceRate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, cadenceID := uuid.New(), uuid.New()
+
+	mock.ExpectQuery("FROM lead_cadences c").
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "enrolled", "active", "completed", "stopped",
+			"done", "skipped", "pending"}).
+			AddRow(cadenceID.String(), "Trade show", 10, 2, 6, 2, 20, 5, 4))
+
+	performance, err := NewLeadCadenceRepository(db).Performance(context.Background(), orgID)
+	require.NoError(t, err)
+	require.Len(t, performance, 1)
+	assert.Equal(t, cadenceID, performance[0].CadenceID)
+	assert.Equal(t, 75.0, performance[0].CompletionRate)
+	assert.Equal(t, 5, performance[0].TasksSkipped)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+type leadCaptureFormRepository struct {
+	db *sql.DB
+}
+
+func NewLeadCaptureFormRepository(db *sql.DB) types.LeadCaptureFormRepository {
+	return &leadCaptureFormRepository{db: db}
+}
+
+const leadCaptureFormColumns = `id, organization_id, name, source_id, team_id, dedup_window_minutes, merge_strategy, active, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLeadCaptureForm(row rowScanner) (*types.LeadCaptureForm, error) {
+	var form types.LeadCaptureForm
+	err := row.Scan(
+		&form.ID, &form.OrganizationID, &form.Name, &form.SourceID, &form.TeamID,
+		&form.DedupWindowMinutes, &form.MergeStrategy, &form.Active, &form.CreatedAt, &form.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &form, nil
+}
+
+func (r *leadCaptureFormRepository) Create(ctx context.Context, form types.LeadCaptureForm) (*types.LeadCaptureForm, error) {
+	query := `
+		INSERT INTO lead_capture_forms (id, organization_id, name, source_id, team_id, dedup_window_minutes, merge_strategy, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + leadCaptureFormColumns
+
+	created, err := scanLeadCaptureForm(r.db.QueryRowContext(ctx, query,
+		form.ID, form.OrganizationID, form.Name, form.SourceID, form.TeamID,
+		form.DedupWindowMinutes, form.MergeStrategy, form.Active, form.CreatedAt, form.UpdatedAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead capture form: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *leadCaptureFormRepository) FindByID(ctx context.Context, id uuid.UUID) (*types.LeadCaptureForm, error) {
+	query := `SELECT ` + leadCaptureFormColumns + ` FROM lead_capture_forms WHERE id = $1`
+
+	form, err := scanLeadCaptureForm(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead capture form not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get lead capture form: %w", err)
+	}
+
+	return form, nil
+}
+
+func (r *leadCaptureFormRepository) FindAll(ctx context.Context, filter types.LeadCaptureFormFilter) ([]*types.LeadCaptureForm, error) {
+	where, args := leadCaptureFormWhere(filter)
+	query := `SELECT ` + leadCaptureFormColumns + ` FROM lead_capture_forms WHERE ` + where + ` ORDER BY name`
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead capture forms: %w", err)
+	}
+	defer rows.Close()
+
+	var forms []*types.LeadCaptureForm
+	for rows.Next() {
+		form, err := scanLeadCaptureForm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead capture form: %w", err)
+		}
+		forms = append(forms, form)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead capture forms: %w", err)
+	}
+
+	return forms, nil
+}
+
+func (r *leadCaptureFormRepository) Update(ctx context.Context, form types.LeadCaptureForm) (*types.LeadCaptureForm, error) {
+	query := `
+		UPDATE lead_capture_forms
+		SET name = $1, source_id = $2, team_id = $3, dedup_window_minutes = $4, merge_strategy = $5, active = $6, updated_at = $7
+		WHERE id = $8 AND organization_id = $9
+		RETURNING ` + leadCaptureFormColumns
+
+	updated, err := scanLeadCaptureForm(r.db.QueryRowContext(ctx, query,
+		form.Name, form.SourceID, form.TeamID, form.DedupWindowMinutes, form.MergeStrategy, form.Active, form.UpdatedAt,
+		form.ID, form.OrganizationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead capture form not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to update lead capture form: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *leadCaptureFormRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM lead_capture_forms WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete lead capture form: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("lead capture form not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Count counts lead capture forms matching the filter criteria
+func (r *leadCaptureFormRepository) Count(ctx context.Context, filter types.LeadCaptureFormFilter) (int, error) {
+	if filter.OrganizationID == uuid.Nil {
+		return 0, errors.New("organization_id is required")
+	}
+
+	where, args := leadCaptureFormWhere(filter)
+	query := `SELECT COUNT(*) FROM lead_capture_forms WHERE ` + where
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count lead capture forms: %w", err)
+	}
+
+	return count, nil
+}
+
+// leadCaptureFormWhere builds the predicate shared by FindAll and Count
+func leadCaptureFormWhere(filter types.LeadCaptureFormFilter) (string, []interface{}) {
+	where := "organization_id = $1"
+	args := []interface{}{filter.OrganizationID}
+
+	if filter.Name != nil && *filter.Name != "" {
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
+		where += " AND " + database.ILike("name", len(args))
+	}
+
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		where += fmt.Sprintf(" AND active = $%d", len(args))
+	}
+
+	return where, args
+}
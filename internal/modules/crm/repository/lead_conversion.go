@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type leadConversionRepository struct {
+	db *sql.DB
+}
+
+func NewLeadConversionRepository(db *sql.DB) types.LeadConversionRepository {
+	return &leadConversionRepository{db: db}
+}
+
+// convertingLead holds the lead fields conversion reads
+type convertingLead struct {
+	name        string
+	contactName sql.NullString
+	email       sql.NullString
+	contactID   uuid.NullUUID
+	companyID   uuid.NullUUID
+	teamID      uuid.NullUUID
+	assignedTo  uuid.NullUUID
+	campaignID  uuid.NullUUID
+	mediumID    uuid.NullUUID
+	leadType    string
+	status      string
+}
+
+// Convert locks the lead, resolves its contact, turns it into an
+// opportunity and raises the quotation in one transaction, so a failure at
+// any step leaves the lead untouched
+func (r *leadConversionRepository) Convert(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID, userID *uuid.UUID, req types.LeadConvertRequest, convertedAt time.Time) (*types.LeadConversion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lead convertingLead
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, contact_name, email, contact_id, company_id, team_id,
+			COALESCE(assigned_to, user_id), campaign_id, medium_id, lead_type, status
+		FROM leads
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`,
+		leadID, orgID,
+	).Scan(&lead.name, &lead.contactName, &lead.email, &lead.contactID, &lead.companyID, &lead.teamID,
+		&lead.assignedTo, &lead.campaignID, &lead.mediumID, &lead.leadType, &lead.status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to lock lead: %w", err)
+	}
+
+	if lead.leadType != string(types.LeadTypeLead) {
+		return nil, fmt.Errorf("%w: lead is already an opportunity", types.ErrLeadNotConvertible)
+	}
+	if !types.LeadStatus(lead.status).IsOpen() {
+		return nil, fmt.Errorf("%w: lead is %s", types.ErrLeadNotConvertible, lead.status)
+	}
+
+	conversion := &types.LeadConversion{
+		LeadID:          leadID,
+		OrganizationID:  orgID,
+		OpportunityName: lead.name,
+		ConvertedAt:     convertedAt,
+		ConvertedBy:     userID,
+	}
+	if req.OpportunityName != nil {
+		conversion.OpportunityName = *req.OpportunityName
+	}
+
+	contactID, created, err := resolveConversionContact(ctx, tx, orgID, leadID, lead, req, userID)
+	if err != nil {
+		return nil, err
+	}
+	conversion.ContactID = contactID
+	conversion.ContactCreated = created
+
+	assignedTo := lead.assignedTo
+	if req.AssignedTo != nil {
+		assignedTo = uuid.NullUUID{UUID: *req.AssignedTo, Valid: true}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE leads SET
+			lead_type = 'opportunity',
+			status = 'in_progress',
+			contact_id = $1,
+			name = $2,
+			expected_revenue = COALESCE($3, expected_revenue),
+			date_deadline = COALESCE($4, date_deadline),
+			assigned_to = $5,
+			user_id = COALESCE($5, user_id),
+			date_conversion = $6,
+			date_open = COALESCE(date_open, $6),
+			updated_at = $6,
+			updated_by = $7,
+			version = version + 1
+		WHERE id = $8`,
+		contactID, conversion.OpportunityName, req.ExpectedRevenue, req.DateDeadline, assignedTo,
+		convertedAt, userID, leadID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert lead: %w", err)
+	}
+
+	if req.Quotation != nil {
+		quotationID := uuid.New()
+		name := "Q/" + strings.ToUpper(quotationID.String()[:8])
+		if err := createConversionQuotation(ctx, tx, orgID, quotationID, name, leadID, contactID, lead, assignedTo, *req.Quotation, userID, convertedAt); err != nil {
+			return nil, err
+		}
+		conversion.QuotationID = &quotationID
+		conversion.QuotationName = &name
+	}
+
+	// Activities and attachments reference the lead by ID and carry over
+	// to the opportunity as they are
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT count(*) FROM activities WHERE organization_id = $1 AND res_model = 'leads' AND res_id = $2),
+			(SELECT count(*) FROM attachments WHERE organization_id = $1 AND res_model = 'leads' AND res_id = $2)`,
+		orgID, leadID,
+	).Scan(&conversion.Activities, &conversion.Attachments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lead activities and attachments: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead conversion: %w", err)
+	}
+	return conversion, nil
+}
+
+// resolveConversionContact returns the contact the opportunity is linked to
+// and whether it was created for it. Existing contacts are marked as
+// customers.
+func resolveConversionContact(ctx context.Context, tx *sql.Tx, orgID, leadID uuid.UUID, lead convertingLead, req types.LeadConvertRequest, userID *uuid.UUID) (uuid.UUID, bool, error) {
+	var contactID uuid.UUID
+	switch {
+	case req.ContactID != nil:
+		contactID = *req.ContactID
+	case lead.contactID.Valid:
+		contactID = lead.contactID.UUID
+	case req.MatchExisting && lead.email.Valid && lead.email.String != "":
+		err := tx.QueryRowContext(ctx, `
+			SELECT id FROM contacts
+			WHERE organization_id = $1 AND lower(email) = lower($2) AND deleted_at IS NULL
+			ORDER BY is_company, created_at
+			LIMIT 1`,
+			orgID, lead.email.String,
+		).Scan(&contactID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, false, fmt.Errorf("failed to match contact: %w", err)
+		}
+	}
+
+	if contactID != uuid.Nil {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE contacts SET is_customer = true, updated_at = now(), updated_by = COALESCE($3, updated_by)
+			WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`,
+			contactID, orgID, userID,
+		)
+		if err != nil {
+			return uuid.Nil, false, fmt.Errorf("failed to link contact: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return uuid.Nil, false, errors.New("contact not found or access denied")
+		}
+		return contactID, false, nil
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO contacts (
+			organization_id, company_id, contact_type, name, email, phone, mobile, website,
+			street, street2, city, state_id, zip, country_id, is_customer, user_id, team_id,
+			created_by, updated_by
+		)
+		SELECT organization_id, company_id, 'person', COALESCE(NULLIF(contact_name, ''), name), email, phone, mobile, website,
+			street, street2, city, state_id, zip, country_id, true, COALESCE(assigned_to, user_id), team_id,
+			$2, $2
+		FROM leads
+		WHERE id = $1
+		RETURNING id`,
+		leadID, userID,
+	).Scan(&contactID)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to create contact: %w", err)
+	}
+	return contactID, true, nil
+}
+
+// createConversionQuotation raises a draft quotation for the opportunity
+func createConversionQuotation(ctx context.Context, tx *sql.Tx, orgID, quotationID uuid.UUID, name string, leadID, contactID uuid.UUID, lead convertingLead, assignedTo uuid.NullUUID, req types.LeadQuotationRequest, userID *uuid.UUID, convertedAt time.Time) error {
+	var total float64
+	for _, line := range req.Lines {
+		total += line.Quantity * line.PriceUnit
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO sales_orders (
+			id, organization_id, company_id, name, date_order, validity_date, partner_id, partner_invoice_id,
+			partner_shipping_id, amount_untaxed, amount_total, state, user_id, team_id, origin, campaign_id,
+			medium_id, note, opportunity_id, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $7, $8, $8, 'draft', $9, $10, $11, $12, $13, $14, $15, $16, $16)`,
+		quotationID, orgID, lead.companyID, name, convertedAt, req.ValidityDate, contactID, total,
+		assignedTo, lead.teamID, lead.name, lead.campaignID, lead.mediumID, req.Note, leadID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create quotation: %w", err)
+	}
+
+	for i, line := range req.Lines {
+		subtotal := line.Quantity * line.PriceUnit
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO sales_order_lines (
+				organization_id, order_id, sequence, name, product_id, product_uom_qty, price_unit,
+				price_subtotal, price_total, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $9)`,
+			orgID, quotationID, (i+1)*10, line.Name, line.ProductID, line.Quantity, line.PriceUnit, subtotal, userID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create quotation line: %w", err)
+		}
+	}
+	return nil
+}
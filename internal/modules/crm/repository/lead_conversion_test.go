@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var convertingLeadColumns = []string{
+	"name", "contact_name", "email", "contact_id", "company_id", "team_id",
+	"assigned_to", "campaign_id", "medium_id", "lead_type", "status",
+}
+
+func TestConvertCreatesContactAndQuotation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID, userID, contactID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	convertedAt := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+	req := types.LeadConvertRequest{
+		Quotation: &types.LeadQuotationRequest{Lines: []types.LeadQuotationLine{
+			{Name: "Implementation", Quantity: 2, PriceUnit: 1500},
+		}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, contact_name").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(convertingLeadColumns).
+			AddRow("Website inquiry", "Ada Lovelace", "ada@example.com", nil, nil, nil, nil, nil, nil, "lead", "new"))
+	mock.ExpectQuery("INSERT INTO contacts").
+		WithArgs(leadID, &userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(contactID.String()))
+	mock.ExpectExec("UPDATE leads SET").
+		WithArgs(contactID, "Website inquiry", nil, nil, uuid.NullUUID{}, convertedAt, &userID, leadID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sales_orders").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sales_order_lines").
+		WithArgs(orgID, sqlmock.AnyArg(), 10, "Implementation", nil, 2.0, 1500.0, 3000.0, &userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT").
+		WithArgs(orgID, leadID).
+		WillReturnRows(sqlmock.NewRows([]string{"activities", "attachments"}).AddRow(3, 1))
+	mock.ExpectCommit()
+
+	conversion, err := NewLeadConversionRepository(db).Convert(context.Background(), orgID, leadID, &userID, req, convertedAt)
+	require.NoError(t, err)
+	assert.Equal(t, contactID, conversion.ContactID)
+	assert.True(t, conversion.ContactCreated)
+	require.NotNil(t, conversion.QuotationID)
+	assert.Equal(t, 3, conversion.Activities)
+	assert.Equal(t, 1, conversion.Attachments)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConvertLinksExistingContactByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID, contactID := uuid.New(), uuid.New(), uuid.New()
+	convertedAt := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, contact_name").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(convertingLeadColumns).
+			AddRow("Website inquiry", nil, "Ada@Example.com", nil, nil, nil, nil, nil, nil, "lead", "in_progress"))
+	mock.ExpectQuery("SELECT id FROM contacts").
+		WithArgs(orgID, "Ada@Example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(contactID.String()))
+	mock.ExpectExec("UPDATE contacts SET is_customer = true").
+		WithArgs(contactID, orgID, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE leads SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT").
+		WithArgs(orgID, leadID).
+		WillReturnRows(sqlmock.NewRows([]string{"activities", "attachments"}).AddRow(0, 0))
+	mock.ExpectCommit()
+
+	conversion, err := NewLeadConversionRepository(db).Convert(context.Background(), orgID, leadID, nil,
+		types.LeadConvertRequest{MatchExisting: true}, convertedAt)
+	require.NoError(t, err)
+	assert.Equal(t, contactID, conversion.ContactID)
+	assert.False(t, conversion.ContactCreated)
+	assert.Nil(t, conversion.QuotationID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConvertRejectsOpportunities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, contact_name").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(convertingLeadColumns).
+			AddRow("Renewal", nil, nil, nil, nil, nil, nil, nil, nil, "opportunity", "in_progress"))
+	mock.ExpectRollback()
+
+	_, err = NewLeadConversionRepository(db).Convert(context.Background(), orgID, leadID, nil, types.LeadConvertRequest{}, time.Now())
+	assert.ErrorIs(t, err, types.ErrLeadNotConvertible)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
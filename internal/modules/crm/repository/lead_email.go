@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadEmailRepository struct {
+	db *sql.DB
+}
+
+func NewLeadEmailRepository(db *sql.DB) types.LeadEmailRepository {
+	return &leadEmailRepository{db: db}
+}
+
+const leadEmailInboxColumns = `id, organization_id, name, address, provider, token, signing_key,
+	source_id, team_id, active, created_at, updated_at, created_by, updated_by`
+
+const leadEmailMessageColumns = `m.id, m.organization_id, m.inbox_id, m.lead_id, m.activity_id, m.thread_id,
+	m.message_id, m.in_reply_to, m."references", m.from_email, m.from_name, m.to_emails, m.subject,
+	m.body_text, m.body_html, m.received_at, m.created_at`
+
+func scanLeadEmailInbox(row rowScanner) (*types.LeadEmailInbox, error) {
+	var inbox types.LeadEmailInbox
+	err := row.Scan(&inbox.ID, &inbox.OrganizationID, &inbox.Name, &inbox.Address, &inbox.Provider,
+		&inbox.Token, &inbox.SigningKey, &inbox.SourceID, &inbox.TeamID, &inbox.Active,
+		&inbox.CreatedAt, &inbox.UpdatedAt, &inbox.CreatedBy, &inbox.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	inbox.HasSigning = inbox.SigningKey != nil && *inbox.SigningKey != ""
+	return &inbox, nil
+}
+
+func scanLeadEmailMessage(row rowScanner) (*types.LeadEmailMessage, error) {
+	var msg types.LeadEmailMessage
+	var references, to pq.StringArray
+	err := row.Scan(&msg.ID, &msg.OrganizationID, &msg.InboxID, &msg.LeadID, &msg.ActivityID, &msg.ThreadID,
+		&msg.MessageID, &msg.InReplyTo, &references, &msg.FromEmail, &msg.FromName, &to, &msg.Subject,
+		&msg.BodyText, &msg.BodyHTML, &msg.ReceivedAt, &msg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.References = references
+	msg.ToEmails = to
+	return &msg, nil
+}
+
+func (r *leadEmailRepository) CreateInbox(ctx context.Context, inbox types.LeadEmailInbox) (*types.LeadEmailInbox, error) {
+	query := `
+		INSERT INTO lead_email_inboxes (
+			id, organization_id, name, address, provider, token, signing_key,
+			source_id, team_id, active, created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + leadEmailInboxColumns
+
+	created, err := scanLeadEmailInbox(r.db.QueryRowContext(ctx, query,
+		inbox.ID, inbox.OrganizationID, inbox.Name, inbox.Address, inbox.Provider, inbox.Token, inbox.SigningKey,
+		inbox.SourceID, inbox.TeamID, inbox.Active, inbox.CreatedAt, inbox.UpdatedAt, inbox.CreatedBy, inbox.UpdatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead email inbox: %w", err)
+	}
+	return created, nil
+}
+
+func (r *leadEmailRepository) FindInbox(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.LeadEmailInbox, error) {
+	query := `SELECT ` + leadEmailInboxColumns + ` FROM lead_email_inboxes WHERE id = $1 AND organization_id = $2`
+
+	inbox, err := scanLeadEmailInbox(r.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead email inbox not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to find lead email inbox: %w", err)
+	}
+	return inbox, nil
+}
+
+func (r *leadEmailRepository) FindInboxByToken(ctx context.Context, token string) (*types.LeadEmailInbox, error) {
+	query := `SELECT ` + leadEmailInboxColumns + ` FROM lead_email_inboxes WHERE token = $1 AND active`
+
+	inbox, err := scanLeadEmailInbox(r.db.QueryRowContext(ctx, query, token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrLeadEmailInboxNotFound
+		}
+		return nil, fmt.Errorf("failed to find lead email inbox: %w", err)
+	}
+	return inbox, nil
+}
+
+func (r *leadEmailRepository) ListInboxes(ctx context.Context, orgID uuid.UUID) ([]types.LeadEmailInbox, error) {
+	query := `SELECT ` + leadEmailInboxColumns + ` FROM lead_email_inboxes WHERE organization_id = $1 ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead email inboxes: %w", err)
+	}
+	defer rows.Close()
+
+	inboxes := []types.LeadEmailInbox{}
+	for rows.Next() {
+		inbox, err := scanLeadEmailInbox(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead email inbox: %w", err)
+		}
+		inboxes = append(inboxes, *inbox)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead email inboxes: %w", err)
+	}
+
+	return inboxes, nil
+}
+
+func (r *leadEmailRepository) UpdateInbox(ctx context.Context, inbox types.LeadEmailInbox) (*types.LeadEmailInbox, error) {
+	query := `
+		UPDATE lead_email_inboxes SET
+			name = $1, address = $2, provider = $3, token = $4, signing_key = $5,
+			source_id = $6, team_id = $7, active = $8, updated_at = $9, updated_by = $10
+		WHERE id = $11 AND organization_id = $12
+		RETURNING ` + leadEmailInboxColumns
+
+	updated, err := scanLeadEmailInbox(r.db.QueryRowContext(ctx, query,
+		inbox.Name, inbox.Address, inbox.Provider, inbox.Token, inbox.SigningKey,
+		inbox.SourceID, inbox.TeamID, inbox.Active, inbox.UpdatedAt, inbox.UpdatedBy,
+		inbox.ID, inbox.OrganizationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead email inbox not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to update lead email inbox: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *leadEmailRepository) DeleteInbox(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM lead_email_inboxes WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lead email inbox: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("lead email inbox not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *leadEmailRepository) FindMessage(ctx context.Context, orgID uuid.UUID, messageID string) (*types.LeadEmailMessage, error) {
+	query := `
+		SELECT ` + leadEmailMessageColumns + `
+		FROM lead_email_messages m
+		WHERE m.organization_id = $1 AND m.message_id = $2`
+
+	msg, err := scanLeadEmailMessage(r.db.QueryRowContext(ctx, query, orgID, messageID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find lead email: %w", err)
+	}
+	return msg, nil
+}
+
+func (r *leadEmailRepository) FindReplied(ctx context.Context, orgID uuid.UUID, messageIDs []string) (*types.LeadEmailMessage, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + leadEmailMessageColumns + `
+		FROM lead_email_messages m
+		JOIN leads l ON l.id = m.lead_id
+		WHERE m.organization_id = $1
+			AND m.message_id = ANY($2)
+			AND l.deleted_at IS NULL
+		ORDER BY m.received_at DESC
+		LIMIT 1`
+
+	msg, err := scanLeadEmailMessage(r.db.QueryRowContext(ctx, query, orgID, pq.Array(messageIDs)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find replied lead email: %w", err)
+	}
+	return msg, nil
+}
+
+func (r *leadEmailRepository) CreateMessage(ctx context.Context, msg types.LeadEmailMessage, activity types.Activity) (*types.LeadEmailMessage, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO activities (
+			id, organization_id, activity_type, summary, note, date_deadline, user_id, assigned_to,
+			res_model, res_id, state, done_date, created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		activity.ID, activity.OrganizationID, activity.ActivityType, activity.Summary, activity.Note,
+		activity.DateDeadline, activity.UserID, activity.AssignedTo, activity.ResModel, activity.ResID,
+		activity.State, activity.DoneDate, activity.CreatedAt, activity.UpdatedAt, activity.CreatedBy, activity.UpdatedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead email activity: %w", err)
+	}
+
+	query := `
+		INSERT INTO lead_email_messages AS m (
+			id, organization_id, inbox_id, lead_id, activity_id, thread_id, message_id, in_reply_to,
+			"references", from_email, from_name, to_emails, subject, body_text, body_html, received_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (organization_id, message_id) WHERE message_id IS NOT NULL DO NOTHING
+		RETURNING ` + leadEmailMessageColumns
+
+	created, err := scanLeadEmailMessage(tx.QueryRowContext(ctx, query,
+		msg.ID, msg.OrganizationID, msg.InboxID, msg.LeadID, activity.ID, msg.ThreadID, msg.MessageID, msg.InReplyTo,
+		pq.Array(msg.References), msg.FromEmail, msg.FromName, pq.Array(msg.ToEmails), msg.Subject,
+		msg.BodyText, msg.BodyHTML, msg.ReceivedAt, msg.CreatedAt,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrLeadEmailDuplicate
+		}
+		return nil, fmt.Errorf("failed to create lead email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead email: %w", err)
+	}
+	return created, nil
+}
+
+func (r *leadEmailRepository) ListByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadEmailMessage, error) {
+	query := `
+		SELECT ` + leadEmailMessageColumns + `
+		FROM lead_email_messages m
+		WHERE m.organization_id = $1 AND m.lead_id = $2
+		ORDER BY m.received_at, m.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead emails: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []types.LeadEmailMessage{}
+	for rows.Next() {
+		msg, err := scanLeadEmailMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead email: %w", err)
+		}
+		messages = append(messages, *msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead emails: %w", err)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var leadEmailMessageRowColumns = []string{"id", "organization_id", "inbox_id", "lead_id", "activity_id", "thread_id",
+	"message_id", "in_reply_to", "references", "from_email", "from_name", "to_emails", "subject",
+	"body_text", "body_html", "received_at", "created_at"}
+
+func TestFindRepliedMatchesThreadOfLiveLead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, id, leadID, threadID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	received := time.Date(2025, 3, 28, 16, 0, 0, 0, time.UTC)
+	ids := []string{"quote-1@acme.test", "inquiry-0@example.com"}
+
+	mock.ExpectQuery(`m.message_id = ANY\(\$2\)\s+AND l.deleted_at IS NULL`).
+		WithArgs(orgID, pq.Array(ids)).
+		WillReturnRows(sqlmock.NewRows(leadEmailMessageRowColumns).
+			AddRow(id.String(), orgID.String(), nil, leadID.String(), nil, threadID.String(),
+				"inquiry-0@example.com", nil, "{}", "jose@example.com", nil, "{sales@acme.test}", "Pricing",
+				"How much for 40 seats?", nil, received, received))
+
+	msg, err := NewLeadEmailRepository(db).FindReplied(context.Background(), orgID, ids)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, leadID, msg.LeadID)
+	assert.Equal(t, threadID, msg.ThreadID)
+	assert.Equal(t, []string{"sales@acme.test"}, msg.ToEmails)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindRepliedWithoutThreadSkipsQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	msg, err := NewLeadEmailRepository(db).FindReplied(context.Background(), uuid.New(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindInboxByTokenUnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM lead_email_inboxes WHERE token = \\$1 AND active").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = NewLeadEmailRepository(db).FindInboxByToken(context.Background(), "missing")
+	assert.ErrorIs(t, err, types.ErrLeadEmailInboxNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMessageRollsBackDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 31, 9, 15, 0, 0, time.UTC)
+	orgID, leadID := uuid.New(), uuid.New()
+	messageID, resModel := "reply-2@example.com", "leads"
+	msg := types.LeadEmailMessage{
+		ID: uuid.New(), OrganizationID: orgID, LeadID: leadID, MessageID: &messageID,
+		FromEmail: "jose@example.com", Subject: "Re: Pricing", ReceivedAt: now, CreatedAt: now,
+	}
+	msg.ThreadID = msg.ID
+	activity := types.Activity{
+		ID: uuid.New(), OrganizationID: orgID, ActivityType: types.ActivityTypeEmail, Summary: "Re: Pricing",
+		ResModel: &resModel, ResID: &leadID, State: types.ActivityStateDone, DoneDate: &now,
+		CreatedAt: now, UpdatedAt: now,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO activities").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`ON CONFLICT \(organization_id, message_id\) WHERE message_id IS NOT NULL DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows(leadEmailMessageRowColumns))
+	mock.ExpectRollback()
+
+	_, err = NewLeadEmailRepository(db).CreateMessage(context.Background(), msg, activity)
+	assert.ErrorIs(t, err, types.ErrLeadEmailDuplicate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
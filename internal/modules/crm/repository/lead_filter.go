@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	types "github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// leadListColumns is the column list every lead query reads, and Create
+// writes, so what is written is always what is read back
+const leadListColumns = `id, organization_id, company_id, name, contact_name, email, phone, mobile,
+		contact_id, user_id, team_id, lead_type, stage_id, priority, source_id,
+		medium_id, campaign_id, expected_revenue, probability, recurring_revenue,
+		recurring_plan, date_open, date_closed, date_deadline, date_last_stage_update,
+		active, status, assigned_to, won_status, lost_reason_id, street, street2, city, state_id, zip,
+		country_id, website, description, tag_ids, color, created_at, updated_at,
+		created_by, updated_by, deleted_at, custom_fields, metadata,
+		system_probability, manual_probability, probability_overridden, version`
+
+// leadListDest returns the scan destinations of leadListColumns
+func leadListDest(lead *types.Lead) []interface{} {
+	return []interface{}{
+		&lead.ID,
+		&lead.OrganizationID,
+		&lead.CompanyID,
+		&lead.Name,
+		&lead.ContactName,
+		&lead.Email,
+		&lead.Phone,
+		&lead.Mobile,
+		&lead.ContactID,
+		&lead.UserID,
+		&lead.TeamID,
+		&lead.LeadType,
+		&lead.StageID,
+		&lead.Priority,
+		&lead.SourceID,
+		&lead.MediumID,
+		&lead.CampaignID,
+		&lead.ExpectedRevenue,
+		&lead.Probability,
+		&lead.RecurringRevenue,
+		&lead.RecurringPlan,
+		&lead.DateOpen,
+		&lead.DateClosed,
+		&lead.DateDeadline,
+		&lead.DateLastStageUpdate,
+		&lead.Active,
+		&lead.Status,
+		&lead.AssignedTo,
+		&lead.WonStatus,
+		&lead.LostReasonID,
+		&lead.Street,
+		&lead.Street2,
+		&lead.City,
+		&lead.StateID,
+		&lead.Zip,
+		&lead.CountryID,
+		&lead.Website,
+		&lead.Description,
+		&lead.TagIDs,
+		&lead.Color,
+		&lead.CreatedAt,
+		&lead.UpdatedAt,
+		&lead.CreatedBy,
+		&lead.UpdatedBy,
+		&lead.DeletedAt,
+		&lead.CustomFields,
+		&lead.Metadata,
+		&lead.SystemProbability,
+		&lead.ManualProbability,
+		&lead.ProbabilityOverridden,
+		&lead.Version,
+	}
+}
+
+// leadFilterQuery is a LeadFilter compiled to SQL.
+// FindAll and Count both build on the same compiled filter so that the
+// listed rows and the reported total always agree.
+type leadFilterQuery struct {
+	Where    string
+	Args     []interface{}
+	Computed []computed.Field
+	OrderBy  string
+	Dialect  database.Dialect
+}
+
+// compileLeadFilter translates a LeadFilter into a WHERE clause and its
+// arguments, written with $n placeholders; Bind renders a query built on it
+// for the dialect
+func compileLeadFilter(filter types.LeadFilter, dialect database.Dialect) leadFilterQuery {
+	dialect = database.DialectOrDefault(dialect)
+
+	var conditions []string
+	var args []interface{}
+
+	add := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+	addLike := func(column string, value *string) {
+		if value != nil && *value != "" {
+			args = append(args, database.LikePattern(*value, filter.MatchMode))
+			conditions = append(conditions, dialect.ILike(column, len(args)))
+		}
+	}
+	addUUID := func(column string, value *uuid.UUID) {
+		if value != nil && *value != uuid.Nil {
+			add(column+" = $%d", *value)
+		}
+	}
+
+	conditions = append(conditions, "deleted_at IS NULL")
+
+	// Organization filter (required)
+	add("organization_id = $%d", filter.OrganizationID)
+
+	// Text filters
+	addLike("name", filter.Name)
+	addLike("email", filter.Email)
+	addLike("phone", filter.Phone)
+	addLike("contact_name", filter.ContactName)
+	addLike("mobile", filter.Mobile)
+	addLike("city", filter.City)
+
+	// Reference filters
+	addUUID("company_id", filter.CompanyID)
+	addUUID("contact_id", filter.ContactID)
+	addUUID("user_id", filter.UserID)
+	addUUID("team_id", filter.TeamID)
+	addUUID("stage_id", filter.StageID)
+	addUUID("source_id", filter.SourceID)
+	addUUID("medium_id", filter.MediumID)
+	addUUID("campaign_id", filter.CampaignID)
+	addUUID("lost_reason_id", filter.LostReasonID)
+	addUUID("assigned_to", filter.AssignedTo)
+	addUUID("country_id", filter.CountryID)
+	addUUID("state_id", filter.StateID)
+	addUUID("created_by", filter.CreatedBy)
+	addUUID("updated_by", filter.UpdatedBy)
+	if len(filter.TagIDs) > 0 {
+		add("tag_ids && $%d::uuid[]", pq.Array(filter.TagIDs))
+	}
+
+	// Enum filters
+	if filter.LeadType != nil && *filter.LeadType != "" {
+		add("lead_type = $%d", *filter.LeadType)
+	}
+	if filter.Priority != nil && *filter.Priority != "" {
+		add("priority = $%d", *filter.Priority)
+	}
+	if filter.WonStatus != nil && *filter.WonStatus != "" {
+		add("won_status = $%d", *filter.WonStatus)
+	}
+	if filter.Status != nil && *filter.Status != "" {
+		add("status = $%d", *filter.Status)
+	}
+	if filter.Active != nil {
+		add("active = $%d", *filter.Active)
+	}
+	if filter.Color != nil && *filter.Color != "" {
+		add("color = $%d", *filter.Color)
+	}
+
+	// Range filters
+	if filter.ExpectedRevenueMin != nil {
+		add("expected_revenue >= $%d", *filter.ExpectedRevenueMin)
+	}
+	if filter.ExpectedRevenueMax != nil {
+		add("expected_revenue <= $%d", *filter.ExpectedRevenueMax)
+	}
+	if filter.ProbabilityMin != nil {
+		add("probability >= $%d", *filter.ProbabilityMin)
+	}
+	if filter.ProbabilityMax != nil {
+		add("probability <= $%d", *filter.ProbabilityMax)
+	}
+	if filter.DateOpenFrom != nil {
+		add("date_open >= $%d", *filter.DateOpenFrom)
+	}
+	if filter.DateOpenTo != nil {
+		add("date_open <= $%d", *filter.DateOpenTo)
+	}
+	if filter.DateDeadlineFrom != nil {
+		add("date_deadline >= $%d", *filter.DateDeadlineFrom)
+	}
+	if filter.DateDeadlineTo != nil {
+		add("date_deadline <= $%d", *filter.DateDeadlineTo)
+	}
+
+	// Computed field filters; names are checked by the service, unknown ones are ignored
+	for _, name := range sortedKeys(filter.ComputedMin) {
+		if field, ok := computed.Lookup(filter.ComputedFields, name); ok {
+			add("("+field.SQL+") >= $%d", filter.ComputedMin[name])
+		}
+	}
+	for _, name := range sortedKeys(filter.ComputedMax) {
+		if field, ok := computed.Lookup(filter.ComputedFields, name); ok {
+			add("("+field.SQL+") <= $%d", filter.ComputedMax[name])
+		}
+	}
+
+	// Custom field filters, compiled to JSON containment by the service
+	if filter.CustomFieldsContain != "" {
+		args = append(args, filter.CustomFieldsContain)
+		conditions = append(conditions, dialect.JSONContains("custom_fields", len(args)))
+	}
+
+	// Query filter tree, compiled by the service
+	if filter.Condition != nil {
+		var condition string
+		condition, args = filter.Condition.Render(dialect, args)
+		conditions = append(conditions, condition)
+	}
+
+	return leadFilterQuery{
+		Where:    strings.Join(conditions, " AND "),
+		Args:     args,
+		Computed: filter.ComputedFields,
+		OrderBy:  leadOrderBy(filter.SortBy, filter.SortDir),
+		Dialect:  dialect,
+	}
+}
+
+// leadSortColumns maps each sortable field to the expression it orders by.
+// Priorities are stored as names, so they are ranked rather than compared.
+var leadSortColumns = map[types.LeadSortField]string{
+	types.LeadSortCreatedAt:       "created_at",
+	types.LeadSortExpectedRevenue: "expected_revenue",
+	types.LeadSortProbability:     "probability",
+	types.LeadSortDateDeadline:    "date_deadline",
+	types.LeadSortPriority:        "CASE priority WHEN 'low' THEN 0 WHEN 'medium' THEN 1 WHEN 'high' THEN 2 WHEN 'urgent' THEN 3 END",
+}
+
+// leadOrderBy returns the ORDER BY list for a sort, by name when the field
+// is not sortable. Sorted lists break ties by name and id and put missing
+// values last, so pages stay stable.
+func leadOrderBy(field types.LeadSortField, dir types.SortDirection) string {
+	column, ok := leadSortColumns[field]
+	if !ok {
+		return "name ASC"
+	}
+	direction := "ASC"
+	if dir == types.SortDesc {
+		direction = "DESC"
+	}
+	return column + " " + direction + " NULLS LAST, name ASC, id ASC"
+}
+
+// sortedKeys returns map keys in order so compiled SQL and its arguments are deterministic
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SelectSQL returns the list query for the compiled filter, including ordering and pagination.
+// When computed fields are set their values are selected as one trailing jsonb column.
+func (q leadFilterQuery) SelectSQL(limit, offset int) string {
+	return q.selectSQL("", limit, offset)
+}
+
+// PageSQL returns the list query of SelectSQL with the total of all matching
+// rows as a trailing column, counted in the same pass over the leads
+func (q leadFilterQuery) PageSQL(limit, offset int) string {
+	return q.selectSQL(", COUNT(*) OVER() AS total", limit, offset)
+}
+
+func (q leadFilterQuery) selectSQL(extra string, limit, offset int) string {
+	columns := leadListColumns
+	if len(q.Computed) > 0 {
+		columns += ", " + computed.SelectSQL(q.Computed) + " AS computed"
+	}
+	query := "SELECT " + columns + extra + " FROM leads WHERE " + q.Where + " ORDER BY " + q.OrderBy
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return query
+}
+
+// CountSQL returns the count query for the compiled filter
+func (q leadFilterQuery) CountSQL() string {
+	return "SELECT COUNT(*) FROM leads WHERE " + q.Where
+}
+
+// Bind renders a query built on the compiled filter for its dialect and
+// returns the arguments to run it with
+func (q leadFilterQuery) Bind(query string) (string, []interface{}) {
+	return q.Dialect.Rebind(query, q.Args)
+}
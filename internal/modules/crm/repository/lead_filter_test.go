@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+)
+
+// recordingMatcher accepts every query and records the SQL that was executed
+type recordingMatcher struct {
+	queries []string
+}
+
+func (m *recordingMatcher) Match(expectedSQL, actualSQL string) error {
+	m.queries = append(m.queries, actualSQL)
+	return nil
+}
+
+// leadFilterSetters each populate one LeadFilter field
+var leadFilterSetters = []func(f *types.LeadFilter){
+	func(f *types.LeadFilter) { v := "acme"; f.Name = &v },
+	func(f *types.LeadFilter) { v := "sales@acme"; f.Email = &v },
+	func(f *types.LeadFilter) { v := "Paris"; f.City = &v },
+	func(f *types.LeadFilter) { v := uuid.New(); f.StageID = &v },
+	func(f *types.LeadFilter) { v := uuid.New(); f.AssignedTo = &v },
+	func(f *types.LeadFilter) { v := types.LeadPriorityHigh; f.Priority = &v },
+	func(f *types.LeadFilter) { v := types.LeadStatusInProgress; f.Status = &v },
+	func(f *types.LeadFilter) { v := true; f.Active = &v },
+	func(f *types.LeadFilter) { v := 1000.0; f.ExpectedRevenueMin = &v },
+	func(f *types.LeadFilter) { v := time.Now(); f.DateDeadlineTo = &v },
+	func(f *types.LeadFilter) {
+		f.ComputedFields = []computed.Field{weightedRevenue}
+		f.ComputedMin = map[string]float64{"weighted_revenue": 500}
+	},
+	func(f *types.LeadFilter) { f.CustomFieldsContain = `{"industry":"retail"}` },
+}
+
+var weightedRevenue = computed.Field{Name: "weighted_revenue", SQL: "(leads.expected_revenue * leads.probability / 100)"}
+
+func whereClause(t *testing.T, query string) string {
+	start := strings.Index(query, " WHERE ")
+	require.NotEqual(t, -1, start, "query has no WHERE clause: %s", query)
+	where := query[start+len(" WHERE "):]
+	if end := strings.Index(where, " ORDER BY "); end != -1 {
+		where = where[:end]
+	}
+	return where
+}
+
+// TestLeadListAndCountAgree asserts that FindAll and Count run the same
+// predicate against the same table for every combination of filters.
+func TestLeadListAndCountAgree(t *testing.T) {
+	orgID := uuid.New()
+
+	for mask := 0; mask < 1<<len(leadFilterSetters); mask++ {
+		filter := types.LeadFilter{OrganizationID: orgID, Limit: 20, Offset: 40}
+		for i, set := range leadFilterSetters {
+			if mask&(1<<i) != 0 {
+				set(&filter)
+			}
+		}
+
+		compiled := compileLeadFilter(filter, database.Postgres)
+		args := make([]driver.Value, len(compiled.Args))
+		for i, arg := range compiled.Args {
+			args[i] = arg
+		}
+
+		matcher := &recordingMatcher{}
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+		require.NoError(t, err)
+
+		mock.ExpectQuery("list").WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("count").WithArgs(args...).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		repo := &LeadRepository{db: db}
+		_, err = repo.FindAll(context.Background(), filter)
+		require.NoError(t, err)
+		_, err = repo.Count(context.Background(), filter)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet(), "mask %b", mask)
+
+		require.Len(t, matcher.queries, 2)
+		listSQL, countSQL := matcher.queries[0], matcher.queries[1]
+		assert.Equal(t, whereClause(t, listSQL), whereClause(t, countSQL), "mask %b", mask)
+		assert.Contains(t, listSQL, " FROM leads WHERE ")
+		assert.Contains(t, countSQL, " FROM leads WHERE ")
+
+		db.Close()
+	}
+}
+
+func TestCompileLeadFilterEscapesText(t *testing.T) {
+	name := "50%_off"
+	compiled := compileLeadFilter(types.LeadFilter{OrganizationID: uuid.New(), Name: &name}, database.Postgres)
+
+	assert.Equal(t, `deleted_at IS NULL AND organization_id = $1 AND name ILIKE $2 ESCAPE '\'`, compiled.Where)
+	assert.Equal(t, `%50\%\_off%`, compiled.Args[1])
+}
+
+func TestCompileLeadFilterComputedFields(t *testing.T) {
+	compiled := compileLeadFilter(types.LeadFilter{
+		OrganizationID: uuid.New(),
+		ComputedFields: []computed.Field{weightedRevenue},
+		ComputedMin:    map[string]float64{"weighted_revenue": 500, "unknown": 1},
+		ComputedMax:    map[string]float64{"weighted_revenue": 9000},
+	}, database.Postgres)
+
+	assert.Equal(t, `deleted_at IS NULL AND organization_id = $1`+
+		` AND ((leads.expected_revenue * leads.probability / 100)) >= $2`+
+		` AND ((leads.expected_revenue * leads.probability / 100)) <= $3`, compiled.Where)
+	assert.Equal(t, []interface{}{500.0, 9000.0}, compiled.Args[1:])
+	assert.Contains(t, compiled.SelectSQL(10, 0), `jsonb_build_object('weighted_revenue', (leads.expected_revenue * leads.probability / 100)) AS computed FROM leads WHERE `)
+
+	plain := compileLeadFilter(types.LeadFilter{OrganizationID: uuid.New()}, database.Postgres)
+	assert.NotContains(t, plain.SelectSQL(10, 0), "computed")
+}
+
+func TestLeadFilterSort(t *testing.T) {
+	orgID := uuid.New()
+
+	plain := compileLeadFilter(types.LeadFilter{OrganizationID: orgID}, database.Postgres)
+	assert.Contains(t, plain.SelectSQL(10, 0), " ORDER BY name ASC LIMIT 10")
+
+	byRevenue := compileLeadFilter(types.LeadFilter{OrganizationID: orgID, SortBy: types.LeadSortExpectedRevenue, SortDir: types.SortDesc}, database.Postgres)
+	assert.Contains(t, byRevenue.SelectSQL(0, 0), " ORDER BY expected_revenue DESC NULLS LAST, name ASC, id ASC")
+
+	byPriority := compileLeadFilter(types.LeadFilter{OrganizationID: orgID, SortBy: types.LeadSortPriority}, database.Postgres)
+	assert.Contains(t, byPriority.SelectSQL(0, 0), " ORDER BY CASE priority WHEN 'low' THEN 0")
+	assert.Contains(t, byPriority.SelectSQL(0, 0), " END ASC NULLS LAST, name ASC, id ASC")
+
+	// Unknown fields never reach the query
+	injected := compileLeadFilter(types.LeadFilter{OrganizationID: orgID, SortBy: "name; DROP TABLE leads"}, database.Postgres)
+	assert.Contains(t, injected.SelectSQL(0, 0), " ORDER BY name ASC")
+	assert.NotContains(t, injected.SelectSQL(0, 0), "DROP")
+}
+
+func TestCompileLeadFilterTags(t *testing.T) {
+	tagIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	compiled := compileLeadFilter(types.LeadFilter{OrganizationID: uuid.New(), TagIDs: tagIDs}, database.Postgres)
+
+	// Leads carrying any of the tags match
+	assert.Equal(t, "deleted_at IS NULL AND organization_id = $1 AND tag_ids && $2::uuid[]", compiled.Where)
+	assert.Equal(t, pq.Array(tagIDs), compiled.Args[1])
+}
+
+func TestCompileLeadFilterCustomFields(t *testing.T) {
+	compiled := compileLeadFilter(types.LeadFilter{OrganizationID: uuid.New(), CustomFieldsContain: `{"industry":"retail"}`}, database.Postgres)
+
+	assert.Equal(t, "deleted_at IS NULL AND organization_id = $1 AND custom_fields @> $2", compiled.Where)
+	assert.Equal(t, `{"industry":"retail"}`, compiled.Args[1])
+}
+
+func TestCompileLeadFilterQuery(t *testing.T) {
+	condition, err := query.Compile(query.Node{Or: []query.Node{
+		{Field: "priority", Op: query.OpEq, Value: []byte(`"urgent"`)},
+		{Field: "expected_revenue", Op: query.OpGt, Value: []byte(`5000`)},
+	}}, types.LeadQuerySchema, nil)
+	require.NoError(t, err)
+
+	active := true
+	compiled := compileLeadFilter(types.LeadFilter{OrganizationID: uuid.New(), Active: &active, Condition: condition}, database.Postgres)
+
+	// The query's placeholders continue after the other filters'
+	assert.Equal(t, "deleted_at IS NULL AND organization_id = $1 AND active = $2 AND (priority = $3 OR expected_revenue > $4)", compiled.Where)
+	assert.Equal(t, []interface{}{"urgent", 5000.0}, compiled.Args[2:])
+}
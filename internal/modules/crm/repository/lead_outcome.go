@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type leadOutcomeRepository struct {
+	db *sql.DB
+}
+
+func NewLeadOutcomeRepository(db *sql.DB) types.LeadOutcomeRepository {
+	return &leadOutcomeRepository{db: db}
+}
+
+// Close marks an open lead won or lost. Won leads move to 100% probability
+// and drop any lost reason; lost leads move to 0% and keep their reason.
+func (r *leadOutcomeRepository) Close(ctx context.Context, outcome types.LeadOutcome) (*types.LeadOutcome, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var wonStatus sql.NullString
+	var expectedRevenue sql.NullFloat64
+	var assignedTo, teamID uuid.NullUUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, status, won_status, expected_revenue, COALESCE(assigned_to, user_id), team_id
+		FROM leads
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`,
+		outcome.LeadID, outcome.OrganizationID,
+	).Scan(&outcome.Name, &status, &wonStatus, &expectedRevenue, &assignedTo, &teamID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to lock lead: %w", err)
+	}
+	if !types.LeadStatus(status).IsOpen() || (wonStatus.Valid && wonStatus.String != string(types.LeadWonStatusOngoing)) {
+		return nil, fmt.Errorf("%w: lead is %s", types.ErrLeadClosed, status)
+	}
+
+	probability := 100
+	leadStatus := types.LeadStatusWon
+	if outcome.WonStatus == types.LeadWonStatusLost {
+		probability = 0
+		leadStatus = types.LeadStatusLost
+		err = tx.QueryRowContext(ctx, `
+			SELECT name FROM lost_reasons WHERE id = $1 AND organization_id = $2 AND active = true`,
+			outcome.LostReasonID, outcome.OrganizationID,
+		).Scan(&outcome.LostReason)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: lost reason %s does not exist or is archived", types.ErrInvalidLeadOutcome, outcome.LostReasonID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find lost reason: %w", err)
+		}
+	} else {
+		outcome.LostReasonID = nil
+	}
+
+	if outcome.ExpectedRevenue == nil && expectedRevenue.Valid {
+		outcome.ExpectedRevenue = &expectedRevenue.Float64
+	}
+	if assignedTo.Valid {
+		outcome.AssignedTo = &assignedTo.UUID
+	}
+	if teamID.Valid {
+		outcome.TeamID = &teamID.UUID
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE leads SET
+			status = $1,
+			won_status = $2,
+			lost_reason_id = $3,
+			probability = $4,
+			expected_revenue = $5,
+			date_closed = $6,
+			updated_at = $6,
+			updated_by = $7,
+			version = version + 1
+		WHERE id = $8`,
+		leadStatus, outcome.WonStatus, outcome.LostReasonID, probability, outcome.ExpectedRevenue,
+		outcome.ClosedAt, outcome.ClosedBy, outcome.LeadID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close lead: %w", err)
+	}
+
+	if outcome.Note != nil {
+		summary := "Marked as won"
+		if outcome.WonStatus == types.LeadWonStatusLost {
+			summary = "Marked as lost: " + outcome.LostReason
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO activities (id, organization_id, activity_type, summary, note, user_id, res_model, res_id,
+				state, done_date, created_at, updated_at, created_by, updated_by)
+			VALUES ($1, $2, 'note', $3, $4, $5, 'leads', $6, 'done', $7, $7, $7, $5, $5)`,
+			uuid.New(), outcome.OrganizationID, summary, *outcome.Note, outcome.ClosedBy, outcome.LeadID, outcome.ClosedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to log lead outcome note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead outcome: %w", err)
+	}
+	return &outcome, nil
+}
+
+// forecastAggregates selects the lead count, average probability, win rate
+// and Brier score of the closed leads matching where, for ProbabilityForecast
+func forecastAggregates(probability, where string) string {
+	return fmt.Sprintf(`COUNT(*) FILTER (WHERE %[2]s),
+			COALESCE(AVG(%[1]s) FILTER (WHERE %[2]s), 0)::float8,
+			COALESCE(AVG(won * 100) FILTER (WHERE %[2]s), 0)::float8,
+			COALESCE(AVG(power(%[1]s / 100.0 - won, 2)) FILTER (WHERE %[2]s), 0)::float8`, probability, where)
+}
+
+// ProbabilityAccuracy scores the probabilities the closed leads had before
+// closing, which closing keeps, against whether they were won
+func (r *leadOutcomeRepository) ProbabilityAccuracy(ctx context.Context, filter types.LeadProbabilityAccuracyFilter) (*types.LeadProbabilityAccuracy, error) {
+	args := []interface{}{filter.OrganizationID}
+	where := "organization_id = $1 AND deleted_at IS NULL AND won_status IN ('won', 'lost')"
+	if filter.TeamID != nil {
+		args = append(args, *filter.TeamID)
+		where += fmt.Sprintf(" AND team_id = $%d", len(args))
+	}
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		where += fmt.Sprintf(" AND date_closed >= $%d", len(args))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		where += fmt.Sprintf(" AND date_closed < $%d", len(args))
+	}
+
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE won = 1),
+			` + forecastAggregates("system_probability", "system_probability IS NOT NULL") + `,
+			` + forecastAggregates("manual_probability", "probability_overridden") + `,
+			` + forecastAggregates("system_probability", "probability_overridden AND system_probability IS NOT NULL") + `
+		FROM (
+			SELECT system_probability, manual_probability, probability_overridden,
+				CASE WHEN won_status = 'won' THEN 1 ELSE 0 END AS won
+			FROM leads
+			WHERE ` + where + `
+		) closed`
+
+	var accuracy types.LeadProbabilityAccuracy
+	dest := []interface{}{&accuracy.ClosedLeads, &accuracy.WonLeads}
+	for _, forecast := range []*types.ProbabilityForecast{&accuracy.System, &accuracy.Manual, &accuracy.OverriddenSystem} {
+		dest = append(dest, &forecast.Leads, &forecast.AverageProbability, &forecast.WinRate, &forecast.BrierScore)
+	}
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to query lead probability accuracy: %w", err)
+	}
+	return &accuracy, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var closingLeadColumns = []string{"name", "status", "won_status", "expected_revenue", "assigned_to", "team_id"}
+
+func TestCloseLostRecordsReasonAndNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID, userID, reasonID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	closedAt := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+	note := "Went with a cheaper vendor"
+	outcome := types.LeadOutcome{
+		LeadID:         leadID,
+		OrganizationID: orgID,
+		WonStatus:      types.LeadWonStatusLost,
+		LostReasonID:   &reasonID,
+		Note:           &note,
+		ClosedAt:       closedAt,
+		ClosedBy:       &userID,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, status, won_status").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(closingLeadColumns).
+			AddRow("Website inquiry", "in_progress", "ongoing", 5000.0, userID.String(), nil))
+	mock.ExpectQuery("SELECT name FROM lost_reasons").
+		WithArgs(&reasonID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Price"))
+	mock.ExpectExec("UPDATE leads SET").
+		WithArgs(types.LeadStatusLost, types.LeadWonStatusLost, &reasonID, 0, sqlmock.AnyArg(), closedAt, &userID, leadID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activities").
+		WithArgs(sqlmock.AnyArg(), orgID, "Marked as lost: Price", note, &userID, leadID, closedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	closed, err := NewLeadOutcomeRepository(db).Close(context.Background(), outcome)
+	require.NoError(t, err)
+	assert.Equal(t, "Website inquiry", closed.Name)
+	assert.Equal(t, "Price", closed.LostReason)
+	require.NotNil(t, closed.ExpectedRevenue)
+	assert.Equal(t, 5000.0, *closed.ExpectedRevenue)
+	assert.Equal(t, &userID, closed.AssignedTo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseRejectsArchivedLostReason(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID, reasonID := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, status, won_status").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(closingLeadColumns).AddRow("Renewal", "new", nil, nil, nil, nil))
+	mock.ExpectQuery("SELECT name FROM lost_reasons").
+		WithArgs(&reasonID, orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectRollback()
+
+	_, err = NewLeadOutcomeRepository(db).Close(context.Background(), types.LeadOutcome{
+		LeadID:         leadID,
+		OrganizationID: orgID,
+		WonStatus:      types.LeadWonStatusLost,
+		LostReasonID:   &reasonID,
+		ClosedAt:       time.Now(),
+	})
+	assert.ErrorIs(t, err, types.ErrInvalidLeadOutcome)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCloseRejectsClosedLead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name, status, won_status").
+		WithArgs(leadID, orgID).
+		WillReturnRows(sqlmock.NewRows(closingLeadColumns).AddRow("Renewal", "won", "won", 1200.0, nil, nil))
+	mock.ExpectRollback()
+
+	_, err = NewLeadOutcomeRepository(db).Close(context.Background(), types.LeadOutcome{
+		LeadID:         leadID,
+		OrganizationID: orgID,
+		WonStatus:      types.LeadWonStatusWon,
+		ClosedAt:       time.Now(),
+	})
+	assert.ErrorIs(t, err, types.ErrLeadClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProbabilityAccuracyScoresSystemAndManual(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, teamID := uuid.New(), uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	columns := []string{"closed", "won"}
+	for _, forecast := range []string{"system", "manual", "overridden_system"} {
+		columns = append(columns, forecast+"_leads", forecast+"_average", forecast+"_win_rate", forecast+"_brier")
+	}
+	mock.ExpectQuery(`(?s)FILTER \(WHERE probability_overridden\).*FROM leads\s+WHERE organization_id = \$1 AND deleted_at IS NULL `+
+		`AND won_status IN \('won', 'lost'\) AND team_id = \$2 AND date_closed >= \$3\s`).
+		WithArgs(orgID, teamID, from).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(10, 4,
+			9, 42.0, 44.4, 0.21,
+			3, 70.0, 66.7, 0.12,
+			3, 30.0, 66.7, 0.35))
+
+	accuracy, err := NewLeadOutcomeRepository(db).ProbabilityAccuracy(context.Background(), types.LeadProbabilityAccuracyFilter{
+		OrganizationID: orgID,
+		TeamID:         &teamID,
+		DateFrom:       &from,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 10, accuracy.ClosedLeads)
+	assert.Equal(t, 4, accuracy.WonLeads)
+	assert.Equal(t, types.ProbabilityForecast{Leads: 9, AverageProbability: 42, WinRate: 44.4, BrierScore: 0.21}, accuracy.System)
+	assert.Equal(t, types.ProbabilityForecast{Leads: 3, AverageProbability: 70, WinRate: 66.7, BrierScore: 0.12}, accuracy.Manual)
+	assert.Equal(t, 0.35, accuracy.OverriddenSystem.BrierScore)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type leadPipelineSnapshotRepository struct {
+	db *sql.DB
+}
+
+func NewLeadPipelineSnapshotRepository(db *sql.DB) types.LeadPipelineSnapshotRepository {
+	return &leadPipelineSnapshotRepository{db: db}
+}
+
+// Capture records where each open lead stands, then totals them per team
+// and stage, in one transaction
+func (r *leadPipelineSnapshotRepository) Capture(ctx context.Context, weekStart time.Time) (*types.LeadPipelineSnapshotRun, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO lead_pipeline_snapshot_leads (
+			organization_id, week_start, lead_id, team_id, stage_id, stage_sequence, value, probability
+		)
+		SELECT l.organization_id, $1, l.id, l.team_id, l.stage_id, s.sequence,
+			COALESCE(l.expected_revenue, 0), COALESCE(l.probability, 0)
+		FROM leads l
+		LEFT JOIN lead_stages s ON s.id = l.stage_id
+		WHERE l.deleted_at IS NULL AND COALESCE(l.active, true)
+			AND l.status IN ('new', 'in_progress')
+			AND NOT EXISTS (
+				SELECT 1 FROM lead_pipeline_snapshots p
+				WHERE p.organization_id = l.organization_id AND p.week_start = $1
+			)
+		ON CONFLICT DO NOTHING`,
+		weekStart,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot pipeline leads: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO lead_pipeline_snapshots (
+			organization_id, week_start, team_id, team_name, stage_id, stage_name, stage_sequence,
+			lead_count, total_value, weighted_value
+		)
+		SELECT x.organization_id, $1, x.team_id, t.name, x.stage_id, s.name, x.stage_sequence,
+			COUNT(*), SUM(x.value), ROUND(SUM(x.value * x.probability / 100.0), 2)
+		FROM lead_pipeline_snapshot_leads x
+		LEFT JOIN sales_teams t ON t.id = x.team_id
+		LEFT JOIN lead_stages s ON s.id = x.stage_id
+		WHERE x.week_start = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM lead_pipeline_snapshots p
+				WHERE p.organization_id = x.organization_id AND p.week_start = $1
+			)
+		GROUP BY x.organization_id, x.team_id, t.name, x.stage_id, s.name, x.stage_sequence
+		RETURNING organization_id, lead_count`,
+		weekStart,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total pipeline snapshot: %w", err)
+	}
+	run := &types.LeadPipelineSnapshotRun{WeekStart: weekStart}
+	orgs := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var orgID uuid.UUID
+		var count int
+		if err := rows.Scan(&orgID, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan pipeline snapshot: %w", err)
+		}
+		orgs[orgID] = true
+		run.Leads += count
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating pipeline snapshots: %w", err)
+	}
+	rows.Close()
+	run.Organizations = len(orgs)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit pipeline snapshot: %w", err)
+	}
+	return run, nil
+}
+
+func (r *leadPipelineSnapshotRepository) LatestWeek(ctx context.Context, orgID uuid.UUID, onOrBefore time.Time) (time.Time, error) {
+	var week sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(week_start) FROM lead_pipeline_snapshots
+		WHERE organization_id = $1 AND week_start <= $2`,
+		orgID, onOrBefore,
+	).Scan(&week)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find pipeline snapshot week: %w", err)
+	}
+	if !week.Valid {
+		return time.Time{}, types.ErrNoPipelineSnapshot
+	}
+	return week.Time.UTC(), nil
+}
+
+func (r *leadPipelineSnapshotRepository) Totals(ctx context.Context, orgID uuid.UUID, weekStart time.Time, teamID *uuid.UUID) ([]types.LeadPipelineSnapshotTotal, error) {
+	args := []interface{}{orgID, weekStart}
+	where := "organization_id = $1 AND week_start = $2"
+	if teamID != nil {
+		args = append(args, *teamID)
+		where += " AND team_id = $3"
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT team_id, COALESCE(team_name, ''), stage_id, COALESCE(stage_name, ''), stage_sequence,
+			lead_count, total_value::float8, weighted_value::float8
+		FROM lead_pipeline_snapshots
+		WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pipeline snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []types.LeadPipelineSnapshotTotal{}
+	for rows.Next() {
+		var t types.LeadPipelineSnapshotTotal
+		if err := rows.Scan(&t.TeamID, &t.TeamName, &t.StageID, &t.StageName, &t.StageSequence,
+			&t.Count, &t.Value, &t.WeightedValue); err != nil {
+			return nil, fmt.Errorf("failed to scan pipeline snapshot: %w", err)
+		}
+		totals = append(totals, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pipeline snapshot: %w", err)
+	}
+
+	return totals, nil
+}
+
+// Movement pairs each lead's place in the two weeks. A lead that stayed in
+// the same team and stage did not move; any other lead entered the stage it
+// is in now and exited the one it was in. Filtering on the team afterwards
+// keeps the leads that came from or went to other teams.
+func (r *leadPipelineSnapshotRepository) Movement(ctx context.Context, orgID uuid.UUID, weekStart, previousWeekStart time.Time, teamID *uuid.UUID) ([]types.LeadPipelineStageMovement, error) {
+	args := []interface{}{orgID, weekStart, previousWeekStart}
+	teamFilter := ""
+	if teamID != nil {
+		args = append(args, *teamID)
+		teamFilter = "WHERE m.team_id = $4"
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH cur AS (
+			SELECT lead_id, team_id, stage_id, stage_sequence FROM lead_pipeline_snapshot_leads
+			WHERE organization_id = $1 AND week_start = $2
+		), prev AS (
+			SELECT lead_id, team_id, stage_id, stage_sequence FROM lead_pipeline_snapshot_leads
+			WHERE organization_id = $1 AND week_start = $3
+		), moved AS (
+			SELECT c.lead_id IS NOT NULL AS in_cur, p.lead_id IS NOT NULL AS in_prev,
+				c.team_id AS cur_team, c.stage_id AS cur_stage, c.stage_sequence AS cur_seq,
+				p.team_id AS prev_team, p.stage_id AS prev_stage, p.stage_sequence AS prev_seq,
+				c.lead_id IS NOT NULL AND p.lead_id IS NOT NULL
+					AND c.team_id IS NOT DISTINCT FROM p.team_id AS same_team
+			FROM cur c
+			FULL JOIN prev p ON p.lead_id = c.lead_id
+			WHERE c.lead_id IS NULL OR p.lead_id IS NULL
+				OR c.team_id IS DISTINCT FROM p.team_id OR c.stage_id IS DISTINCT FROM p.stage_id
+		)
+		SELECT m.team_id, m.stage_id, SUM(m.entered)::int, SUM(m.entered_new)::int, SUM(m.exited)::int,
+			SUM(m.advanced)::int, SUM(m.regressed)::int, SUM(m.closed)::int
+		FROM (
+			SELECT cur_team AS team_id, cur_stage AS stage_id, 1 AS entered,
+				CASE WHEN in_prev THEN 0 ELSE 1 END AS entered_new,
+				0 AS exited, 0 AS advanced, 0 AS regressed, 0 AS closed
+			FROM moved WHERE in_cur
+			UNION ALL
+			SELECT prev_team, prev_stage, 0, 0, 1,
+				CASE WHEN same_team AND cur_seq > prev_seq THEN 1 ELSE 0 END,
+				CASE WHEN same_team AND cur_seq < prev_seq THEN 1 ELSE 0 END,
+				CASE WHEN in_cur THEN 0 ELSE 1 END
+			FROM moved WHERE in_prev
+		) m
+		`+teamFilter+`
+		GROUP BY m.team_id, m.stage_id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pipeline movement: %w", err)
+	}
+	defer rows.Close()
+
+	movement := []types.LeadPipelineStageMovement{}
+	for rows.Next() {
+		var m types.LeadPipelineStageMovement
+		if err := rows.Scan(&m.TeamID, &m.StageID, &m.Entered, &m.EnteredNew, &m.Exited,
+			&m.Advanced, &m.Regressed, &m.Closed); err != nil {
+			return nil, fmt.Errorf("failed to scan pipeline movement: %w", err)
+		}
+		movement = append(movement, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pipeline movement: %w", err)
+	}
+
+	return movement, nil
+}
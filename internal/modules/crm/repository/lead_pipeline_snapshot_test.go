@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestLeadPipelineSnapshotCaptureTotalsOrganizations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	week := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	orgA, orgB := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO lead_pipeline_snapshot_leads .* NOT EXISTS`).
+		WithArgs(week).
+		WillReturnResult(sqlmock.NewResult(0, 6))
+	mock.ExpectQuery(`INSERT INTO lead_pipeline_snapshots .* GROUP BY .* RETURNING organization_id, lead_count`).
+		WithArgs(week).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id", "lead_count"}).
+			AddRow(orgA, 2).AddRow(orgA, 3).AddRow(orgB, 1))
+	mock.ExpectCommit()
+
+	repo := NewLeadPipelineSnapshotRepository(db)
+	run, err := repo.Capture(context.Background(), week)
+	require.NoError(t, err)
+	assert.Equal(t, &types.LeadPipelineSnapshotRun{WeekStart: week, Organizations: 2, Leads: 6}, run)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeadPipelineSnapshotLatestWeekWithoutSnapshot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID := uuid.New()
+	week := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT MAX\(week_start\) FROM lead_pipeline_snapshots`).
+		WithArgs(orgID, week).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	repo := NewLeadPipelineSnapshotRepository(db)
+	_, err = repo.LatestWeek(context.Background(), orgID, week)
+	assert.ErrorIs(t, err, types.ErrNoPipelineSnapshot)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeadPipelineSnapshotMovementFiltersTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, teamID, stageID := uuid.New(), uuid.New(), uuid.New()
+	week := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	previous := week.AddDate(0, 0, -7)
+	mock.ExpectQuery(`FULL JOIN prev p ON p\.lead_id = c\.lead_id.*WHERE m\.team_id = \$4\s+GROUP BY m\.team_id, m\.stage_id`).
+		WithArgs(orgID, week, previous, teamID).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "stage_id", "entered", "entered_new", "exited", "advanced", "regressed", "closed"}).
+			AddRow(teamID, stageID, 3, 1, 2, 1, 0, 1))
+
+	repo := NewLeadPipelineSnapshotRepository(db)
+	movement, err := repo.Movement(context.Background(), orgID, week, previous, &teamID)
+	require.NoError(t, err)
+	require.Len(t, movement, 1)
+	assert.Equal(t, types.LeadPipelineMovement{Entered: 3, EnteredNew: 1, Exited: 2, Advanced: 1, Closed: 1}, movement[0].LeadPipelineMovement)
+	assert.Equal(t, &stageID, movement[0].StageID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadReassignRepository struct {
+	db *sql.DB
+}
+
+func NewLeadReassignRepository(db *sql.DB) types.LeadReassignRepository {
+	return &leadReassignRepository{db: db}
+}
+
+func (r *leadReassignRepository) OpenLeads(ctx context.Context, orgID, userID uuid.UUID, limit int) ([]types.LeadReassignTarget, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, COALESCE(name, '')
+		FROM leads
+		WHERE organization_id = $1 AND assigned_to = $2
+			AND deleted_at IS NULL AND COALESCE(active, true)
+			AND status IN ('new', 'in_progress')
+		ORDER BY created_at, id
+		LIMIT $3`,
+		orgID, userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []types.LeadReassignTarget{}
+	for rows.Next() {
+		var lead types.LeadReassignTarget
+		if err := rows.Scan(&lead.ID, &lead.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan open lead: %w", err)
+		}
+		leads = append(leads, lead)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open leads: %w", err)
+	}
+
+	return leads, nil
+}
+
+// Reassign skips the leads that changed owner or closed since they were
+// planned. The loads move by the number of leads each owner gained or lost.
+func (r *leadReassignRepository) Reassign(ctx context.Context, batch types.LeadReassignBatch) ([]uuid.UUID, error) {
+	leadIDs := make([]uuid.UUID, len(batch.Assignments))
+	userIDs := make([]uuid.UUID, len(batch.Assignments))
+	names := make(map[uuid.UUID]string, len(batch.Assignments))
+	for i, a := range batch.Assignments {
+		leadIDs[i], userIDs[i] = a.LeadID, a.ToUserID
+		names[a.LeadID] = a.LeadName
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE leads l SET assigned_to = a.user_id, updated_at = $4, updated_by = $5, version = l.version + 1
+		FROM unnest($2::uuid[], $3::uuid[]) AS a(lead_id, user_id)
+		WHERE l.id = a.lead_id AND l.organization_id = $1 AND l.assigned_to = $6
+			AND l.deleted_at IS NULL AND l.status IN ('new', 'in_progress')
+		RETURNING l.id, a.user_id`,
+		batch.OrganizationID, pq.Array(leadIDs), pq.Array(userIDs), batch.AssignedAt, batch.AssignedBy, batch.FromUserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign leads: %w", err)
+	}
+	var reassigned, owners []uuid.UUID
+	var leadNames []string
+	var entries []types.LeadAuditEntry
+	for rows.Next() {
+		var leadID, userID uuid.UUID
+		if err := rows.Scan(&leadID, &userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reassigned lead: %w", err)
+		}
+		reassigned = append(reassigned, leadID)
+		owners = append(owners, userID)
+		leadNames = append(leadNames, names[leadID])
+
+		change := audit.FieldChange{Field: "assigned_to", Old: batch.FromUserID, New: userID}
+		leadEntries, err := types.NewLeadAuditEntries(batch.OrganizationID, leadID, []audit.FieldChange{change},
+			types.LeadAuditBulkUpdate, batch.AssignedBy, batch.AssignedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, leadEntries...)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating reassigned leads: %w", err)
+	}
+	rows.Close()
+
+	if len(reassigned) == 0 {
+		return reassigned, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO assignment_history (
+			organization_id, target_model, target_id, target_name, assigned_to_type, assigned_to_id,
+			previous_assigned_to_id, assignment_reason, metadata, assigned_at, assigned_by
+		)
+		SELECT $1, 'leads', a.lead_id, NULLIF(a.name, ''), 'user', a.user_id,
+			$5, 'reassignment', jsonb_strip_nulls(jsonb_build_object('note', NULLIF($6::text, ''))), $7, $8
+		FROM unnest($2::uuid[], $3::uuid[], $4::text[]) AS a(lead_id, user_id, name)`,
+		batch.OrganizationID, pq.Array(reassigned), pq.Array(owners), pq.Array(leadNames),
+		batch.FromUserID, batch.Reason, batch.AssignedAt, batch.AssignedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create assignment history: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_assignment_load (
+			organization_id, user_id, target_model, active_assignments, total_assignments, last_assigned_at
+		)
+		SELECT $1, a.user_id, 'leads', COUNT(*), COUNT(*), $3
+		FROM unnest($2::uuid[]) AS a(user_id)
+		GROUP BY a.user_id
+		ON CONFLICT (organization_id, user_id, target_model)
+		DO UPDATE SET
+			active_assignments = user_assignment_load.active_assignments + EXCLUDED.active_assignments,
+			total_assignments = user_assignment_load.total_assignments + EXCLUDED.total_assignments,
+			last_assigned_at = EXCLUDED.last_assigned_at,
+			updated_at = CURRENT_TIMESTAMP`,
+		batch.OrganizationID, pq.Array(owners), batch.AssignedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user assignment load: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_assignment_load
+		SET active_assignments = GREATEST(active_assignments - $3, 0),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE organization_id = $1 AND user_id = $2 AND target_model = 'leads'`,
+		batch.OrganizationID, batch.FromUserID, len(reassigned),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release previous owner load: %w", err)
+	}
+
+	if err := insertLeadAuditEntries(ctx, tx, entries); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead reassignment: %w", err)
+	}
+
+	return reassigned, nil
+}
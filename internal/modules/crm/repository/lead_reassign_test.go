@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestLeadReassignRecordsHistoryAndMovesLoads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, from, to, actor := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	kept, moved := uuid.New(), uuid.New()
+	now := time.Now()
+	batch := types.LeadReassignBatch{
+		OrganizationID: orgID,
+		FromUserID:     from,
+		Assignments: []types.LeadReassignment{
+			{LeadID: kept, LeadName: "Closed meanwhile", ToUserID: to},
+			{LeadID: moved, LeadName: "Acme", ToUserID: to},
+		},
+		Reason:     "Leaving the company",
+		AssignedBy: &actor,
+		AssignedAt: now,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE leads l SET assigned_to = a\.user_id.*WHERE l\.id = a\.lead_id AND l\.organization_id = \$1 AND l\.assigned_to = \$6`).
+		WithArgs(orgID, sqlmock.AnyArg(), sqlmock.AnyArg(), now, &actor, from).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(moved, to))
+	mock.ExpectExec(`INSERT INTO assignment_history .* 'reassignment'`).
+		WithArgs(orgID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), from, "Leaving the company", now, &actor).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_assignment_load .* ON CONFLICT`).
+		WithArgs(orgID, sqlmock.AnyArg(), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user_assignment_load\s+SET active_assignments = GREATEST\(active_assignments - \$3, 0\)`).
+		WithArgs(orgID, from, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`INSERT INTO lead_audit_log`).ExpectExec().
+		WithArgs(sqlmock.AnyArg(), orgID, moved, "assigned_to", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			types.LeadAuditBulkUpdate, &actor, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	reassigned, err := NewLeadReassignRepository(db).Reassign(context.Background(), batch)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{moved}, reassigned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
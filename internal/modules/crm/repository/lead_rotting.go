@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+type leadRottingRepository struct {
+	db *sql.DB
+}
+
+func NewLeadRottingRepository(db *sql.DB) types.LeadRottingRepository {
+	return &leadRottingRepository{db: db}
+}
+
+// RottingLeads takes a lead's last touch as the latest of its stage entry,
+// an activity logged or done on it, its last (re)assignment and its last
+// edit by a user. Rotations that failed do not hold the lead back.
+func (r *leadRottingRepository) RottingLeads(ctx context.Context, now time.Time, limit int) ([]types.RottingLead, error) {
+	query := `
+		SELECT l.id, l.organization_id, COALESCE(l.name, ''), s.id, s.name, l.team_id, t.team_leader_id,
+			l.assigned_to, s.rotting_days, touch.at
+		FROM leads l
+		JOIN lead_stages s ON s.id = l.stage_id
+		LEFT JOIN sales_teams t ON t.id = l.team_id
+		CROSS JOIN LATERAL (
+			SELECT GREATEST(
+				COALESCE(l.date_last_stage_update, l.created_at),
+				(SELECT MAX(GREATEST(a.created_at, a.done_date)) FROM activities a
+					WHERE a.res_model = 'leads' AND a.res_id = l.id),
+				(SELECT MAX(h.assigned_at) FROM assignment_history h
+					WHERE h.target_model = 'leads' AND h.target_id = l.id),
+				(SELECT MAX(g.changed_at) FROM lead_audit_log g
+					WHERE g.lead_id = l.id AND g.changed_by IS NOT NULL)
+			) AS at
+		) touch
+		WHERE s.rotting_days IS NOT NULL
+			AND l.deleted_at IS NULL AND COALESCE(l.active, true)
+			AND l.status IN ('new', 'in_progress')
+			AND l.assigned_to IS NOT NULL
+			AND touch.at <= $1 - make_interval(days => s.rotting_days)
+			AND NOT EXISTS (
+				SELECT 1 FROM lead_rotations r
+				WHERE r.lead_id = l.id AND r.rotated_at >= touch.at AND r.result <> 'failed'
+			)
+		ORDER BY touch.at
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rotting leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []types.RottingLead{}
+	for rows.Next() {
+		var lead types.RottingLead
+		if err := rows.Scan(&lead.LeadID, &lead.OrganizationID, &lead.LeadName, &lead.StageID, &lead.StageName,
+			&lead.TeamID, &lead.TeamLeaderID, &lead.AssignedTo, &lead.RottingDays, &lead.LastTouchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rotting lead: %w", err)
+		}
+		leads = append(leads, lead)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rotting leads: %w", err)
+	}
+
+	return leads, nil
+}
+
+func (r *leadRottingRepository) RecordRotation(ctx context.Context, rotation types.LeadRotation) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO lead_rotations (
+			id, organization_id, lead_id, stage_id, team_id, previous_assigned_to,
+			assigned_to, result, rotting_days, last_touched_at, rotated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		rotation.ID, rotation.OrganizationID, rotation.LeadID, rotation.StageID, rotation.TeamID,
+		rotation.PreviousAssignedTo, rotation.AssignedTo, rotation.Result, rotation.RottingDays,
+		rotation.LastTouchedAt, rotation.RotatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record lead rotation: %w", err)
+	}
+	return nil
+}
+
+// RotationStats counts, per stage, the leads rotated in the period. A lead
+// whose rotation failed before succeeding counts once per attempt as failed.
+func (r *leadRottingRepository) RotationStats(ctx context.Context, filter types.LeadRotationStatsFilter) ([]types.LeadRotationStats, error) {
+	args := []interface{}{filter.OrganizationID}
+	where := "r.organization_id = $1"
+
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		where += fmt.Sprintf(" AND r.rotated_at >= $%d", len(args))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		where += fmt.Sprintf(" AND r.rotated_at < $%d", len(args))
+	}
+
+	query := `
+		SELECT r.stage_id, COALESCE(s.name, ''),
+			COUNT(*) FILTER (WHERE r.result <> 'failed'),
+			COUNT(*) FILTER (WHERE r.result = 'reassigned'),
+			COUNT(*) FILTER (WHERE r.result = 'returned_to_pool'),
+			COUNT(*) FILTER (WHERE r.result = 'kept'),
+			COUNT(*) FILTER (WHERE r.result = 'no_rule'),
+			COUNT(*) FILTER (WHERE r.result = 'failed'),
+			COALESCE(AVG(EXTRACT(EPOCH FROM (r.rotated_at - r.last_touched_at))) FILTER (WHERE r.result <> 'failed'), 0)::float8
+		FROM lead_rotations r
+		LEFT JOIN lead_stages s ON s.id = r.stage_id
+		WHERE ` + where + `
+		GROUP BY r.stage_id, s.name, s.sequence
+		ORDER BY s.sequence, s.name`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead rotation stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []types.LeadRotationStats{}
+	for rows.Next() {
+		var s types.LeadRotationStats
+		var idleSeconds float64
+		if err := rows.Scan(&s.StageID, &s.StageName, &s.Rotted, &s.Reassigned, &s.Pooled, &s.Kept,
+			&s.NoRule, &s.Failed, &idleSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan lead rotation stats: %w", err)
+		}
+		s.AverageIdleDays = idleSeconds / 86400
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead rotation stats: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestRottingLeadsScansLeadsPastTheirStageRottingDays(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+	leadID, orgID, stageID, owner := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	touched := now.AddDate(0, 0, -8)
+
+	mock.ExpectQuery(`touch.at <= \$1 - make_interval\(days => s.rotting_days\)`).
+		WithArgs(now, 500).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "name", "stage_id", "stage_name", "team_id",
+			"team_leader_id", "assigned_to", "rotting_days", "at"}).
+			AddRow(leadID.String(), orgID.String(), "Website inquiry", stageID.String(), "Qualified", nil,
+				nil, owner.String(), 7, touched))
+
+	leads, err := NewLeadRottingRepository(db).RottingLeads(context.Background(), now, 500)
+	require.NoError(t, err)
+	assert.Equal(t, []types.RottingLead{{LeadID: leadID, OrganizationID: orgID, LeadName: "Website inquiry",
+		StageID: stageID, StageName: "Qualified", AssignedTo: owner, RottingDays: 7, LastTouchedAt: touched}}, leads)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotationStatsAveragesIdleDaysPerStage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, stageID := uuid.New(), uuid.New()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`WHERE r.organization_id = \$1 AND r.rotated_at >= \$2\s+GROUP BY r.stage_id`).
+		WithArgs(orgID, from).
+		WillReturnRows(sqlmock.NewRows([]string{"stage_id", "name", "rotted", "reassigned", "pooled", "kept",
+			"no_rule", "failed", "idle"}).
+			AddRow(stageID.String(), "Qualified", 4, 2, 1, 0, 1, 1, float64(9*86400)))
+
+	stats, err := NewLeadRottingRepository(db).RotationStats(context.Background(),
+		types.LeadRotationStatsFilter{OrganizationID: orgID, DateFrom: &from})
+	require.NoError(t, err)
+	assert.Equal(t, []types.LeadRotationStats{{StageID: stageID, StageName: "Qualified", Rotted: 4, Reassigned: 2,
+		Pooled: 1, NoRule: 1, Failed: 1, AverageIdleDays: 9}}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// Highlight delimiters passed to ts_headline. They are swapped for <mark>
+// tags after the headline is HTML escaped, so lead text cannot inject markup.
+const (
+	highlightStart = "⟦"
+	highlightStop  = "⟧"
+)
+
+// leadHighlightFields are the fields highlighted in search hits, in the
+// order of their headline columns
+var leadHighlightFields = []string{"name", "contact_name", "email", "description"}
+
+type leadSearchRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewLeadSearchRepository creates a lead search repository ranking and
+// highlighting hits as dialect allows; a nil dialect means PostgreSQL
+func NewLeadSearchRepository(db *sql.DB, dialect database.Dialect) types.LeadSearchRepository {
+	return &leadSearchRepository{db: db, dialect: database.DialectOrDefault(dialect)}
+}
+
+// leadSearchTSQuery turns free text into a prefix tsquery matching leads
+// containing every term, so partially typed words still match. A term that
+// is a phone number in any punctuation becomes its bare digits, matching
+// the digits indexed for phone and mobile. It returns "" when the text has
+// no searchable term.
+func leadSearchTSQuery(text string) string {
+	if digits := phoneDigits(text); digits != "" {
+		return digits + ":*"
+	}
+
+	var terms []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '@' && r != '.'
+	}) {
+		if term := strings.Trim(field, "@."); term != "" {
+			terms = append(terms, term+":*")
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+// phoneDigits returns the digits of text when it looks like a phone number:
+// at least three digits and only phone punctuation besides
+func phoneDigits(text string) string {
+	var digits strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case strings.ContainsRune(" +-().", r):
+		default:
+			return ""
+		}
+	}
+	if digits.Len() < 3 {
+		return ""
+	}
+	return digits.String()
+}
+
+// leadSearchMatch compiles the search and its filters to a WHERE clause
+// over leads aliased l. The tsquery is always $2.
+func leadSearchMatch(req types.LeadSearchRequest, tsquery string, filtered bool) (string, []interface{}) {
+	conditions := []string{
+		"l.organization_id = $1",
+		"l.deleted_at IS NULL",
+		"l.search_vector @@ to_tsquery('simple', $2)",
+	}
+	args := []interface{}{req.OrganizationID, tsquery}
+	if filtered {
+		if req.StageID != nil {
+			args = append(args, *req.StageID)
+			conditions = append(conditions, fmt.Sprintf("l.stage_id = $%d", len(args)))
+		}
+		if req.Priority != nil {
+			args = append(args, *req.Priority)
+			conditions = append(conditions, fmt.Sprintf("l.priority = $%d", len(args)))
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// Search returns a page of the leads matching every term of the query, by
+// descending relevance, with matches highlighted. The total and the hits
+// honour the stage and priority filters; the facets do not.
+func (r *leadSearchRepository) Search(ctx context.Context, req types.LeadSearchRequest) (*types.LeadSearchResult, error) {
+	result := &types.LeadSearchResult{
+		Hits: []types.LeadSearchHit{},
+		Facets: types.LeadSearchFacets{
+			Stage:    []types.LeadSearchFacet{},
+			Priority: []types.LeadSearchFacet{},
+		},
+	}
+	tsquery := leadSearchTSQuery(req.Query)
+	if tsquery == "" {
+		return result, nil
+	}
+
+	where, args := leadSearchMatch(req, tsquery, true)
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leads l WHERE "+where, args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("failed to count lead search hits: %w", err)
+	}
+	if result.Total > 0 {
+		hits, err := r.searchHits(ctx, req, where, args)
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = hits
+	}
+
+	facets, err := r.searchFacets(ctx, req, tsquery)
+	if err != nil {
+		return nil, err
+	}
+	result.Facets = *facets
+	return result, nil
+}
+
+// searchHits ranks the matches and highlights only the page returned, as
+// ts_headline reparses the text of every row it is given. Dialects that
+// cannot highlight return hits without highlights.
+func (r *leadSearchRepository) searchHits(ctx context.Context, req types.LeadSearchRequest, where string, args []interface{}) ([]types.LeadSearchHit, error) {
+	args = append(args, req.Limit, req.Offset)
+	headline := func(column, options string) string {
+		return r.dialect.TextHeadline("coalesce("+column+", '')", "to_tsquery('simple', $2)",
+			fmt.Sprintf("%s, StartSel=%s, StopSel=%s", options, highlightStart, highlightStop))
+	}
+	query := `
+		SELECT ` + leadListColumns + `, rank,
+			` + headline("name", "HighlightAll=true") + `,
+			` + headline("contact_name", "HighlightAll=true") + `,
+			` + headline("email", "HighlightAll=true") + `,
+			` + headline("description", "MaxFragments=2, MaxWords=20, MinWords=5") + `
+		FROM (
+			SELECT l.*, ` + r.dialect.TextRank("l.search_vector", "to_tsquery('simple', $2)") + ` AS rank
+			FROM leads l
+			WHERE ` + where + `
+			ORDER BY rank DESC, l.name ASC, l.id ASC
+			LIMIT $` + fmt.Sprint(len(args)-1) + ` OFFSET $` + fmt.Sprint(len(args)) + `
+		) page
+		ORDER BY rank DESC, name ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search leads: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []types.LeadSearchHit{}
+	for rows.Next() {
+		var hit types.LeadSearchHit
+		headlines := make([]string, len(leadHighlightFields))
+		dest := leadListDest(&hit.Lead)
+		dest = append(dest, &hit.Rank)
+		for i := range headlines {
+			dest = append(dest, &headlines[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan lead search hit: %w", err)
+		}
+		for i, field := range leadHighlightFields {
+			if highlighted, ok := highlight(headlines[i]); ok {
+				if hit.Highlights == nil {
+					hit.Highlights = make(map[string]string)
+				}
+				hit.Highlights[field] = highlighted
+			}
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during lead search iteration: %w", err)
+	}
+	return hits, nil
+}
+
+// highlight escapes a headline and marks its matches, reporting false when
+// it has none
+func highlight(headline string) (string, bool) {
+	if !strings.Contains(headline, highlightStart) {
+		return "", false
+	}
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>"), true
+}
+
+// searchFacets counts all matches, regardless of the stage and priority
+// filters, by stage and by priority, most frequent first. The two counts
+// are a union rather than grouping sets, which CockroachDB lacks.
+func (r *leadSearchRepository) searchFacets(ctx context.Context, req types.LeadSearchRequest, tsquery string) (*types.LeadSearchFacets, error) {
+	where, args := leadSearchMatch(req, tsquery, false)
+	query := `
+		SELECT l.stage_id, s.name, NULL AS priority, true AS by_stage, COUNT(*) AS count
+		FROM leads l
+		LEFT JOIN lead_stages s ON s.id = l.stage_id
+		WHERE ` + where + `
+		GROUP BY l.stage_id, s.name
+		UNION ALL
+		SELECT NULL, NULL, l.priority, false, COUNT(*)
+		FROM leads l
+		WHERE ` + where + `
+		GROUP BY l.priority
+		ORDER BY count DESC, name ASC, priority ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lead search facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := &types.LeadSearchFacets{
+		Stage:    []types.LeadSearchFacet{},
+		Priority: []types.LeadSearchFacet{},
+	}
+	for rows.Next() {
+		var stageID uuid.NullUUID
+		var stageName, priority sql.NullString
+		var byStage bool
+		var count int
+		if err := rows.Scan(&stageID, &stageName, &priority, &byStage, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan lead search facet: %w", err)
+		}
+		if byStage {
+			facet := types.LeadSearchFacet{Label: stageName.String, Count: count}
+			if stageID.Valid {
+				facet.Value = stageID.UUID.String()
+			}
+			facets.Stage = append(facets.Stage, facet)
+		} else {
+			facets.Priority = append(facets.Priority, types.LeadSearchFacet{Value: priority.String, Count: count})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during lead search facet iteration: %w", err)
+	}
+	return facets, nil
+}
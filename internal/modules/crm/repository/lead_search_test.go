@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+func TestLeadSearchTSQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"acme", "acme:*"},
+		{"  Acme   Corp ", "Acme:* & Corp:*"},
+		{"jane@acme.com", "jane@acme.com:*"},
+		{"acme.com.", "acme.com:*"},
+		{"o'brien & (sons) | !x:*", "o:* & brien:* & sons:* & x:*"},
+		{"(555) 010-0199", "5550100199:*"},
+		{"+1 555", "1555:*"},
+		{"12", "12:*"},
+		{"müller", "müller:*"},
+		{"&|!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, leadSearchTSQuery(tt.text), tt.text)
+	}
+}
+
+func TestHighlightEscapesLeadText(t *testing.T) {
+	highlighted, ok := highlight("<b>" + highlightStart + "Acme" + highlightStop + "</b> & sons")
+	require.True(t, ok)
+	assert.Equal(t, "&lt;b&gt;<mark>Acme</mark>&lt;/b&gt; &amp; sons", highlighted)
+
+	_, ok = highlight("Acme & sons")
+	assert.False(t, ok, "no match")
+}
+
+func TestSearchFacetsIgnoreFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, stageID := uuid.New(), uuid.New()
+	priority := types.LeadPriorityHigh
+	req := types.LeadSearchRequest{OrganizationID: orgID, Query: "acme", StageID: &stageID, Priority: &priority, Limit: 20}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM leads l WHERE .* l.stage_id = \$3 AND l.priority = \$4`).
+		WithArgs(orgID, "acme:*", stageID, priority).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("UNION ALL").
+		WithArgs(orgID, "acme:*").
+		WillReturnRows(sqlmock.NewRows([]string{"stage_id", "name", "priority", "by_stage", "count"}).
+			AddRow(stageID.String(), "Qualified", nil, true, 3).
+			AddRow(nil, nil, nil, true, 1).
+			AddRow(nil, nil, "medium", false, 4))
+
+	result, err := NewLeadSearchRepository(db, database.Postgres).Search(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+	assert.Empty(t, result.Hits)
+	assert.Equal(t, []types.LeadSearchFacet{
+		{Value: stageID.String(), Label: "Qualified", Count: 3},
+		{Value: "", Count: 1},
+	}, result.Facets.Stage)
+	assert.Equal(t, []types.LeadSearchFacet{{Value: "medium", Count: 4}}, result.Facets.Priority)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchWithoutTermsQueriesNothing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := NewLeadSearchRepository(db, database.Postgres).Search(context.Background(), types.LeadSearchRequest{OrganizationID: uuid.New(), Query: "&&"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+	assert.NotNil(t, result.Hits)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
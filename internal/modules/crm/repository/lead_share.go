@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadShareRepository struct {
+	db *sql.DB
+}
+
+func NewLeadShareRepository(db *sql.DB) types.LeadShareRepository {
+	return &leadShareRepository{db: db}
+}
+
+const leadShareLinkColumns = `id, organization_id, name, token_hash, query, sort_by, sort_dir, fields, expires_at,
+	revoked_at, revoked_by, view_count, last_viewed_at, created_at, created_by`
+
+func scanLeadShareLink(row rowScanner) (*types.LeadShareLink, error) {
+	var link types.LeadShareLink
+	var query []byte
+	err := row.Scan(&link.ID, &link.OrganizationID, &link.Name, &link.TokenHash, &query, &link.SortBy, &link.SortDir,
+		pq.Array(&link.Fields), &link.ExpiresAt, &link.RevokedAt, &link.RevokedBy, &link.ViewCount, &link.LastViewedAt,
+		&link.CreatedAt, &link.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if len(query) > 0 {
+		if err := json.Unmarshal(query, &link.Query); err != nil {
+			return nil, fmt.Errorf("failed to decode share link query: %w", err)
+		}
+	}
+	return &link, nil
+}
+
+func (r *leadShareRepository) CreateShareLink(ctx context.Context, link types.LeadShareLink) (*types.LeadShareLink, error) {
+	var query []byte
+	if link.Query != nil {
+		var err error
+		if query, err = json.Marshal(link.Query); err != nil {
+			return nil, fmt.Errorf("failed to encode share link query: %w", err)
+		}
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO lead_share_links (id, organization_id, name, token_hash, query, sort_by, sort_dir, fields,
+			expires_at, created_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+leadShareLinkColumns,
+		link.ID, link.OrganizationID, link.Name, link.TokenHash, query, link.SortBy, link.SortDir, pq.Array(link.Fields),
+		link.ExpiresAt, link.CreatedAt, link.CreatedBy)
+
+	created, err := scanLeadShareLink(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead share link: %w", err)
+	}
+	return created, nil
+}
+
+func (r *leadShareRepository) ListShareLinks(ctx context.Context, orgID uuid.UUID) ([]types.LeadShareLink, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+leadShareLinkColumns+` FROM lead_share_links
+		WHERE organization_id = $1
+		ORDER BY created_at DESC`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lead share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []types.LeadShareLink{}
+	for rows.Next() {
+		link, err := scanLeadShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead share link: %w", err)
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+func (r *leadShareRepository) RevokeShareLink(ctx context.Context, orgID, id uuid.UUID, revokedBy *uuid.UUID, at time.Time) (*types.LeadShareLink, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE lead_share_links
+		SET revoked_at = COALESCE(revoked_at, $3),
+			revoked_by = CASE WHEN revoked_at IS NULL THEN $4 ELSE revoked_by END
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+leadShareLinkColumns, orgID, id, at, revokedBy)
+
+	link, err := scanLeadShareLink(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead share link not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to revoke lead share link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *leadShareRepository) ViewShareLink(ctx context.Context, tokenHash string, at time.Time) (*types.LeadShareLink, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE lead_share_links
+		SET view_count = view_count + 1, last_viewed_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2
+		RETURNING `+leadShareLinkColumns, tokenHash, at)
+
+	link, err := scanLeadShareLink(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrLeadShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to find lead share link: %w", err)
+	}
+	return link, nil
+}
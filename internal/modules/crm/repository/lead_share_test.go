@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+)
+
+var leadShareLinkRowColumns = []string{"id", "organization_id", "name", "token_hash", "query", "sort_by", "sort_dir", "fields",
+	"expires_at", "revoked_at", "revoked_by", "view_count", "last_viewed_at", "created_at", "created_by"}
+
+func TestViewShareLinkCountsViewsOfLiveLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	id, orgID := uuid.New(), uuid.New()
+	mock.ExpectQuery(`UPDATE lead_share_links\s+SET view_count = view_count \+ 1, last_viewed_at = \$2\s+WHERE token_hash = \$1 AND revoked_at IS NULL AND expires_at > \$2`).
+		WithArgs("hash", now).
+		WillReturnRows(sqlmock.NewRows(leadShareLinkRowColumns).
+			AddRow(id, orgID, "Pipeline", "hash", []byte(`{"field": "priority", "op": "eq", "value": "high"}`), "name", "asc",
+				"{name,expected_revenue}", now.Add(time.Hour), nil, nil, 3, now, now, nil))
+	mock.ExpectQuery(`UPDATE lead_share_links`).
+		WithArgs("expired", now).
+		WillReturnRows(sqlmock.NewRows(leadShareLinkRowColumns))
+
+	repo := NewLeadShareRepository(db)
+	link, err := repo.ViewShareLink(context.Background(), "hash", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "expected_revenue"}, link.Fields)
+	require.NotNil(t, link.Query)
+	assert.Equal(t, query.OpEq, link.Query.Op)
+	assert.Equal(t, 3, link.ViewCount)
+
+	_, err = repo.ViewShareLink(context.Background(), "expired", now)
+	assert.ErrorIs(t, err, types.ErrLeadShareLinkNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,442 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type leadSLARepository struct {
+	db *sql.DB
+}
+
+func NewLeadSLARepository(db *sql.DB) types.LeadSLARepository {
+	return &leadSLARepository{db: db}
+}
+
+const leadSLAPolicyColumns = `id, organization_id, name, stage_id, priority, response_minutes,
+	escalate, active, created_at, updated_at, created_by, updated_by`
+
+const leadSLAClockColumns = `c.id, c.organization_id, c.lead_id, COALESCE(l.name, ''), c.policy_id, c.team_id,
+	c.stage_id, c.priority, c.assigned_to, c.escalate, c.started_at, c.due_at, c.responded_at,
+	c.breached_at, c.escalated_at, c.escalated_to, c.escalation_result`
+
+func scanLeadSLAPolicy(row rowScanner) (*types.LeadSLAPolicy, error) {
+	var policy types.LeadSLAPolicy
+	err := row.Scan(&policy.ID, &policy.OrganizationID, &policy.Name, &policy.StageID, &policy.Priority,
+		&policy.ResponseMinutes, &policy.Escalate, &policy.Active, &policy.CreatedAt, &policy.UpdatedAt,
+		&policy.CreatedBy, &policy.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func scanLeadSLAClock(row rowScanner) (*types.LeadSLAClock, error) {
+	var clock types.LeadSLAClock
+	err := row.Scan(&clock.ID, &clock.OrganizationID, &clock.LeadID, &clock.LeadName, &clock.PolicyID,
+		&clock.TeamID, &clock.StageID, &clock.Priority, &clock.AssignedTo, &clock.Escalate, &clock.StartedAt,
+		&clock.DueAt, &clock.RespondedAt, &clock.BreachedAt, &clock.EscalatedAt, &clock.EscalatedTo,
+		&clock.EscalationResult)
+	if err != nil {
+		return nil, err
+	}
+	return &clock, nil
+}
+
+func leadSLAPolicyError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return types.ErrLeadSLAPolicyExists
+	}
+	return err
+}
+
+func (r *leadSLARepository) CreatePolicy(ctx context.Context, policy types.LeadSLAPolicy) (*types.LeadSLAPolicy, error) {
+	query := `
+		INSERT INTO lead_sla_policies (
+			id, organization_id, name, stage_id, priority, response_minutes,
+			escalate, active, created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + leadSLAPolicyColumns
+
+	created, err := scanLeadSLAPolicy(r.db.QueryRowContext(ctx, query,
+		policy.ID, policy.OrganizationID, policy.Name, policy.StageID, policy.Priority, policy.ResponseMinutes,
+		policy.Escalate, policy.Active, policy.CreatedAt, policy.UpdatedAt, policy.CreatedBy, policy.UpdatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead SLA policy: %w", leadSLAPolicyError(err))
+	}
+	return created, nil
+}
+
+func (r *leadSLARepository) FindPolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.LeadSLAPolicy, error) {
+	query := `SELECT ` + leadSLAPolicyColumns + ` FROM lead_sla_policies WHERE id = $1 AND organization_id = $2`
+
+	policy, err := scanLeadSLAPolicy(r.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead SLA policy not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to find lead SLA policy: %w", err)
+	}
+	return policy, nil
+}
+
+func (r *leadSLARepository) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.LeadSLAPolicy, error) {
+	query := `
+		SELECT ` + leadSLAPolicyColumns + `
+		FROM lead_sla_policies
+		WHERE organization_id = $1
+		ORDER BY stage_id IS NULL, priority IS NULL, name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead SLA policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []types.LeadSLAPolicy{}
+	for rows.Next() {
+		policy, err := scanLeadSLAPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead SLA policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead SLA policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *leadSLARepository) UpdatePolicy(ctx context.Context, policy types.LeadSLAPolicy) (*types.LeadSLAPolicy, error) {
+	query := `
+		UPDATE lead_sla_policies SET
+			name = $1, stage_id = $2, priority = $3, response_minutes = $4,
+			escalate = $5, active = $6, updated_at = $7, updated_by = $8
+		WHERE id = $9 AND organization_id = $10
+		RETURNING ` + leadSLAPolicyColumns
+
+	updated, err := scanLeadSLAPolicy(r.db.QueryRowContext(ctx, query,
+		policy.Name, policy.StageID, policy.Priority, policy.ResponseMinutes,
+		policy.Escalate, policy.Active, policy.UpdatedAt, policy.UpdatedBy,
+		policy.ID, policy.OrganizationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead SLA policy not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to update lead SLA policy: %w", leadSLAPolicyError(err))
+	}
+	return updated, nil
+}
+
+// DeletePolicy removes the policy. Its clocks are kept for compliance
+// reporting and stop escalating.
+func (r *leadSLARepository) DeletePolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM lead_sla_policies WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lead SLA policy: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("lead SLA policy not found: %w", sql.ErrNoRows)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE lead_sla_clocks SET escalate = false
+		WHERE policy_id IS NULL AND organization_id = $1 AND responded_at IS NULL`,
+		orgID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to stop lead SLA escalations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lead SLA policy deletion: %w", err)
+	}
+	return nil
+}
+
+// UntimedEntries matches each open lead's current stage entry with the most
+// specific active policy, a stage match ranking above a priority match.
+// Entries that happened before the policy was created are not timed, so a
+// new policy does not flag the whole backlog as breached.
+func (r *leadSLARepository) UntimedEntries(ctx context.Context, limit int) ([]types.LeadSLAEntry, error) {
+	query := `
+		SELECT l.id, l.organization_id, p.id, l.team_id, l.stage_id, l.priority,
+			COALESCE(l.assigned_to, l.user_id), p.response_minutes, p.escalate, e.entered_at
+		FROM leads l
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(l.date_last_stage_update, l.created_at) AS entered_at
+		) e
+		CROSS JOIN LATERAL (
+			SELECT p.id, p.response_minutes, p.escalate, p.created_at
+			FROM lead_sla_policies p
+			WHERE p.organization_id = l.organization_id AND p.active
+				AND (p.stage_id IS NULL OR p.stage_id = l.stage_id)
+				AND (p.priority IS NULL OR p.priority = l.priority)
+			ORDER BY p.stage_id IS NULL, p.priority IS NULL
+			LIMIT 1
+		) p
+		WHERE l.deleted_at IS NULL AND COALESCE(l.active, true)
+			AND l.status IN ('new', 'in_progress')
+			AND e.entered_at >= p.created_at
+			AND NOT EXISTS (
+				SELECT 1 FROM lead_sla_clocks c
+				WHERE c.lead_id = l.id AND c.started_at = e.entered_at
+			)
+		ORDER BY e.entered_at
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query untimed lead stage entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.LeadSLAEntry{}
+	for rows.Next() {
+		var entry types.LeadSLAEntry
+		if err := rows.Scan(&entry.LeadID, &entry.OrganizationID, &entry.PolicyID, &entry.TeamID, &entry.StageID,
+			&entry.Priority, &entry.AssignedTo, &entry.ResponseMinutes, &entry.Escalate, &entry.EnteredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lead stage entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead stage entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *leadSLARepository) StartClock(ctx context.Context, clock types.LeadSLAClock) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO lead_sla_clocks (
+			id, organization_id, lead_id, policy_id, team_id, stage_id, priority,
+			assigned_to, escalate, started_at, due_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (lead_id, started_at) DO NOTHING`,
+		clock.ID, clock.OrganizationID, clock.LeadID, clock.PolicyID, clock.TeamID, clock.StageID, clock.Priority,
+		clock.AssignedTo, clock.Escalate, clock.StartedAt, clock.DueAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start lead SLA clock: %w", err)
+	}
+	return nil
+}
+
+// RecordResponses takes the first response after the clock started: an
+// activity logged on the lead, a cadence activity done, a later stage
+// change, or the lead closing or being deleted. Received emails and meetings
+// the prospect booked are not responses.
+func (r *leadSLARepository) RecordResponses(ctx context.Context) (int, error) {
+	query := `
+		WITH responses AS (
+			SELECT c.id, LEAST(
+				(SELECT MIN(a.created_at) FROM activities a
+					WHERE a.res_model = 'leads' AND a.res_id = c.lead_id
+						AND a.state <> 'cancelled' AND a.created_at >= c.started_at
+						AND NOT EXISTS (SELECT 1 FROM lead_email_messages m WHERE m.activity_id = a.id)
+						AND NOT EXISTS (SELECT 1 FROM lead_cadence_tasks t WHERE t.activity_id = a.id)
+						AND NOT EXISTS (SELECT 1 FROM lead_bookings b WHERE b.activity_id = a.id)),
+				(SELECT MIN(COALESCE(a.done_date, a.updated_at)) FROM activities a
+					JOIN lead_cadence_tasks t ON t.activity_id = a.id
+					WHERE a.res_model = 'leads' AND a.res_id = c.lead_id
+						AND a.state = 'done' AND COALESCE(a.done_date, a.updated_at) >= c.started_at),
+				CASE WHEN COALESCE(l.date_last_stage_update, l.created_at) > c.started_at
+					THEN COALESCE(l.date_last_stage_update, l.created_at) END,
+				CASE WHEN l.deleted_at IS NOT NULL OR l.status NOT IN ('new', 'in_progress')
+					THEN COALESCE(l.deleted_at, l.date_closed, l.updated_at) END
+			) AS responded_at
+			FROM lead_sla_clocks c
+			JOIN leads l ON l.id = c.lead_id
+			WHERE c.responded_at IS NULL
+		)
+		UPDATE lead_sla_clocks c SET
+			responded_at = r.responded_at,
+			breached_at = CASE WHEN c.breached_at IS NULL AND r.responded_at > c.due_at
+				THEN c.due_at ELSE c.breached_at END
+		FROM responses r
+		WHERE c.id = r.id AND r.responded_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record lead SLA responses: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func (r *leadSLARepository) FlagBreaches(ctx context.Context, now time.Time) ([]types.LeadSLAClock, error) {
+	query := `
+		WITH flagged AS (
+			UPDATE lead_sla_clocks SET breached_at = due_at
+			WHERE responded_at IS NULL AND breached_at IS NULL AND due_at <= $1
+			RETURNING *
+		)
+		SELECT ` + leadSLAClockColumns + `
+		FROM flagged c
+		LEFT JOIN leads l ON l.id = c.lead_id
+		ORDER BY c.due_at`
+
+	return r.queryClocks(ctx, "breached lead SLA clocks", query, now)
+}
+
+func (r *leadSLARepository) PendingEscalations(ctx context.Context, limit int) ([]types.LeadSLAClock, error) {
+	query := `
+		SELECT ` + leadSLAClockColumns + `
+		FROM lead_sla_clocks c
+		LEFT JOIN leads l ON l.id = c.lead_id
+		WHERE c.escalate AND c.breached_at IS NOT NULL
+			AND c.responded_at IS NULL AND c.escalated_at IS NULL
+		ORDER BY c.breached_at
+		LIMIT $1`
+
+	return r.queryClocks(ctx, "lead SLA escalations", query, limit)
+}
+
+func (r *leadSLARepository) MarkEscalated(ctx context.Context, id uuid.UUID, escalatedTo *uuid.UUID, result string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE lead_sla_clocks SET escalated_at = $1, escalated_to = $2, escalation_result = $3
+		WHERE id = $4`,
+		at, escalatedTo, result, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record lead SLA escalation: %w", err)
+	}
+	return nil
+}
+
+func (r *leadSLARepository) Breaches(ctx context.Context, filter types.LeadSLABreachFilter) ([]types.LeadSLAClock, error) {
+	args := []interface{}{filter.OrganizationID}
+	where := "c.organization_id = $1 AND c.breached_at IS NOT NULL"
+
+	if filter.TeamID != nil {
+		args = append(args, *filter.TeamID)
+		where += fmt.Sprintf(" AND c.team_id = $%d", len(args))
+	}
+	if filter.AssignedTo != nil {
+		args = append(args, *filter.AssignedTo)
+		where += fmt.Sprintf(" AND c.assigned_to = $%d", len(args))
+	}
+	if filter.Open {
+		where += " AND c.responded_at IS NULL"
+	}
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		where += fmt.Sprintf(" AND c.breached_at >= $%d", len(args))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		where += fmt.Sprintf(" AND c.breached_at < $%d", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := `
+		SELECT ` + leadSLAClockColumns + `
+		FROM lead_sla_clocks c
+		LEFT JOIN leads l ON l.id = c.lead_id
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY c.breached_at DESC, c.id
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	return r.queryClocks(ctx, "lead SLA breaches", query, args...)
+}
+
+// Compliance counts, per team, the clocks started in the period. A clock
+// responded to by its due time met the target; one flagged as breached
+// missed it, whether or not it was responded to since.
+func (r *leadSLARepository) Compliance(ctx context.Context, filter types.LeadSLAComplianceFilter) ([]types.LeadSLACompliance, error) {
+	args := []interface{}{filter.OrganizationID}
+	where := "c.organization_id = $1"
+
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		where += fmt.Sprintf(" AND c.started_at >= $%d", len(args))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		where += fmt.Sprintf(" AND c.started_at < $%d", len(args))
+	}
+
+	query := `
+		SELECT c.team_id, COALESCE(t.name, ''),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE c.breached_at IS NULL AND c.responded_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE c.breached_at IS NOT NULL),
+			COUNT(*) FILTER (WHERE c.escalated_at IS NOT NULL AND c.escalated_to IS NOT NULL),
+			COALESCE(AVG(EXTRACT(EPOCH FROM (c.responded_at - c.started_at))) FILTER (WHERE c.responded_at IS NOT NULL), 0)::float8
+		FROM lead_sla_clocks c
+		LEFT JOIN sales_teams t ON t.id = c.team_id
+		WHERE ` + where + `
+		GROUP BY c.team_id, t.name
+		ORDER BY t.name NULLS LAST`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead SLA compliance: %w", err)
+	}
+	defer rows.Close()
+
+	compliance := []types.LeadSLACompliance{}
+	for rows.Next() {
+		var c types.LeadSLACompliance
+		var averageSeconds float64
+		if err := rows.Scan(&c.TeamID, &c.TeamName, &c.Total, &c.Met, &c.Breached, &c.Escalated, &averageSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan lead SLA compliance: %w", err)
+		}
+		c.Pending = c.Total - c.Met - c.Breached
+		if decided := c.Met + c.Breached; decided > 0 {
+			c.ComplianceRate = float64(c.Met) / float64(decided) * 100
+		}
+		c.AverageResponseMinutes = averageSeconds / 60
+		compliance = append(compliance, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead SLA compliance: %w", err)
+	}
+
+	return compliance, nil
+}
+
+func (r *leadSLARepository) queryClocks(ctx context.Context, what string, query string, args ...interface{}) ([]types.LeadSLAClock, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	clocks := []types.LeadSLAClock{}
+	for rows.Next() {
+		clock, err := scanLeadSLAClock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lead SLA clock: %w", err)
+		}
+		clocks = append(clocks, *clock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s: %w", what, err)
+	}
+
+	return clocks, nil
+}
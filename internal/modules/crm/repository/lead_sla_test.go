@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+var leadSLAClockRowColumns = []string{"id", "organization_id", "lead_id", "lead_name", "policy_id", "team_id",
+	"stage_id", "priority", "assigned_to", "escalate", "started_at", "due_at", "responded_at",
+	"breached_at", "escalated_at", "escalated_to", "escalation_result"}
+
+func TestFlagBreachesReturnsBreachedClocks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC)
+	id, orgID, leadID, policyID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	started, due := now.Add(-3*time.Hour), now.Add(-time.Hour)
+
+	mock.ExpectQuery("UPDATE lead_sla_clocks SET breached_at = due_at").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(leadSLAClockRowColumns).
+			AddRow(id.String(), orgID.String(), leadID.String(), "Website inquiry", policyID.String(), nil,
+				nil, "urgent", nil, true, started, due, nil, due, nil, nil, nil))
+
+	clocks, err := NewLeadSLARepository(db).FlagBreaches(context.Background(), now)
+	require.NoError(t, err)
+	require.Len(t, clocks, 1)
+	assert.Equal(t, leadID, clocks[0].LeadID)
+	assert.Equal(t, "Website inquiry", clocks[0].LeadName)
+	assert.Equal(t, &policyID, clocks[0].PolicyID)
+	assert.Equal(t, types.LeadPriorityUrgent, clocks[0].Priority)
+	require.NotNil(t, clocks[0].BreachedAt)
+	assert.Equal(t, due, *clocks[0].BreachedAt)
+	assert.Nil(t, clocks[0].RespondedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBreachesFiltersOpenBreachesOfTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, teamID := uuid.New(), uuid.New()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`c.team_id = \$2 AND c.responded_at IS NULL AND c.breached_at >= \$3`).
+		WithArgs(orgID, teamID, from, 25, 50).
+		WillReturnRows(sqlmock.NewRows(leadSLAClockRowColumns))
+
+	breaches, err := NewLeadSLARepository(db).Breaches(context.Background(), types.LeadSLABreachFilter{
+		OrganizationID: orgID,
+		TeamID:         &teamID,
+		Open:           true,
+		DateFrom:       &from,
+		Limit:          25,
+		Offset:         50,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, breaches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComplianceRateLeavesOutPendingClocks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, teamID := uuid.New(), uuid.New()
+
+	mock.ExpectQuery("FROM lead_sla_clocks c").
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "name", "total", "met", "breached", "escalated", "average"}).
+			AddRow(teamID.String(), "Inside sales", 10, 6, 2, 1, 1800.0).
+			AddRow(nil, "", 1, 0, 0, 0, 0.0))
+
+	compliance, err := NewLeadSLARepository(db).Compliance(context.Background(), types.LeadSLAComplianceFilter{OrganizationID: orgID})
+	require.NoError(t, err)
+	require.Len(t, compliance, 2)
+	assert.Equal(t, &teamID, compliance[0].TeamID)
+	assert.Equal(t, 2, compliance[0].Pending)
+	assert.Equal(t, 75.0, compliance[0].ComplianceRate)
+	assert.Equal(t, 30.0, compliance[0].AverageResponseMinutes)
+	assert.Nil(t, compliance[1].TeamID)
+	assert.Equal(t, 1, compliance[1].Pending)
+	assert.Zero(t, compliance[1].ComplianceRate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePolicyRejectsDuplicateTarget(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	priority := types.LeadPriorityHigh
+	policy := types.LeadSLAPolicy{
+		ID:              uuid.New(),
+		OrganizationID:  uuid.New(),
+		Name:            "High priority",
+		Priority:        &priority,
+		ResponseMinutes: 60,
+	}
+
+	mock.ExpectQuery("INSERT INTO lead_sla_policies").
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err = NewLeadSLARepository(db).CreatePolicy(context.Background(), policy)
+	assert.ErrorIs(t, err, types.ErrLeadSLAPolicyExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -59,8 +60,8 @@ func (r *leadSourceRepository) FindAll(ctx context.Context, filter types.LeadSou
 	args = append(args, filter.OrganizationID)
 
 	if filter.Name != nil {
-		query += " AND name LIKE $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", len(args)+1)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	query += " ORDER BY name"
@@ -135,10 +136,9 @@ func (r *leadSourceRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // Count counts lead sources matching the filter criteria
 func (r *leadSourceRepository) Count(ctx context.Context, filter types.LeadSourceFilter) (int, error) {
-	// Get organization ID from context for security
-	orgID, ok := ctx.Value("organizationID").(uuid.UUID)
-	if !ok {
-		return 0, errors.New("organization ID not found in context")
+	orgID := filter.OrganizationID
+	if orgID == uuid.Nil {
+		return 0, errors.New("organization_id is required")
 	}
 
 	query := `SELECT COUNT(*) FROM lead_sources WHERE organization_id = $1`
@@ -146,8 +146,8 @@ func (r *leadSourceRepository) Count(ctx context.Context, filter types.LeadSourc
 	argIndex := 2
 
 	if filter.Name != nil && *filter.Name != "" {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", argIndex)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -20,14 +21,14 @@ func NewLeadStageRepository(db *sql.DB) types.LeadStageRepository {
 }
 
 func (r *leadStageRepository) Create(ctx context.Context, stage types.LeadStage) (*types.LeadStage, error) {
-	query := `INSERT INTO lead_stages (id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, created_at, updated_at`
+	query := `INSERT INTO lead_stages (id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, rotting_days, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, rotting_days, created_at, updated_at`
 
 	var created types.LeadStage
 	err := r.db.QueryRowContext(ctx, query,
 		stage.ID, stage.OrganizationID, stage.Name, stage.Sequence, stage.Probability,
-		stage.Fold, stage.IsWon, stage.Requirements, stage.TeamID, stage.CreatedAt, stage.UpdatedAt).Scan(
+		stage.Fold, stage.IsWon, stage.Requirements, stage.TeamID, stage.RottingDays, stage.CreatedAt, stage.UpdatedAt).Scan(
 		&created.ID, &created.OrganizationID, &created.Name, &created.Sequence, &created.Probability,
-		&created.Fold, &created.IsWon, &created.Requirements, &created.TeamID, &created.CreatedAt, &created.UpdatedAt,
+		&created.Fold, &created.IsWon, &created.Requirements, &created.TeamID, &created.RottingDays, &created.CreatedAt, &created.UpdatedAt,
 	)
 
 	if err != nil {
@@ -38,12 +39,12 @@ func (r *leadStageRepository) Create(ctx context.Context, stage types.LeadStage)
 }
 
 func (r *leadStageRepository) FindByID(ctx context.Context, id uuid.UUID) (*types.LeadStage, error) {
-	query := `SELECT id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, created_at, updated_at FROM lead_stages WHERE id = $1`
+	query := `SELECT id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, rotting_days, created_at, updated_at FROM lead_stages WHERE id = $1`
 
 	var stage types.LeadStage
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&stage.ID, &stage.OrganizationID, &stage.Name, &stage.Sequence, &stage.Probability,
-		&stage.Fold, &stage.IsWon, &stage.Requirements, &stage.TeamID, &stage.CreatedAt, &stage.UpdatedAt,
+		&stage.Fold, &stage.IsWon, &stage.Requirements, &stage.TeamID, &stage.RottingDays, &stage.CreatedAt, &stage.UpdatedAt,
 	)
 
 	if err != nil {
@@ -57,14 +58,14 @@ func (r *leadStageRepository) FindByID(ctx context.Context, id uuid.UUID) (*type
 }
 
 func (r *leadStageRepository) FindAll(ctx context.Context, filter types.LeadStageFilter) ([]*types.LeadStage, error) {
-	query := `SELECT id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, created_at, updated_at FROM lead_stages WHERE organization_id = $1`
+	query := `SELECT id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, rotting_days, created_at, updated_at FROM lead_stages WHERE organization_id = $1`
 
 	var args []interface{}
 	args = append(args, filter.OrganizationID)
 
 	if filter.Name != nil {
-		query += " AND name LIKE $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", len(args)+1)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	if filter.IsWon != nil {
@@ -97,7 +98,7 @@ func (r *leadStageRepository) FindAll(ctx context.Context, filter types.LeadStag
 	for rows.Next() {
 		var stage types.LeadStage
 		if err := rows.Scan(&stage.ID, &stage.OrganizationID, &stage.Name, &stage.Sequence, &stage.Probability,
-			&stage.Fold, &stage.IsWon, &stage.Requirements, &stage.TeamID, &stage.CreatedAt, &stage.UpdatedAt); err != nil {
+			&stage.Fold, &stage.IsWon, &stage.Requirements, &stage.TeamID, &stage.RottingDays, &stage.CreatedAt, &stage.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan lead stage: %w", err)
 		}
 		stages = append(stages, &stage)
@@ -111,14 +112,14 @@ func (r *leadStageRepository) FindAll(ctx context.Context, filter types.LeadStag
 }
 
 func (r *leadStageRepository) Update(ctx context.Context, stage types.LeadStage) (*types.LeadStage, error) {
-	query := `UPDATE lead_stages SET name = $1, sequence = $2, probability = $3, fold = $4, is_won = $5, requirements = $6, team_id = $7, updated_at = $8 WHERE id = $9 RETURNING id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, created_at, updated_at`
+	query := `UPDATE lead_stages SET name = $1, sequence = $2, probability = $3, fold = $4, is_won = $5, requirements = $6, team_id = $7, rotting_days = $8, updated_at = $9 WHERE id = $10 RETURNING id, organization_id, name, sequence, probability, fold, is_won, requirements, team_id, rotting_days, created_at, updated_at`
 
 	var updated types.LeadStage
 	err := r.db.QueryRowContext(ctx, query,
 		stage.Name, stage.Sequence, stage.Probability, stage.Fold, stage.IsWon,
-		stage.Requirements, stage.TeamID, stage.UpdatedAt, stage.ID).Scan(
+		stage.Requirements, stage.TeamID, stage.RottingDays, stage.UpdatedAt, stage.ID).Scan(
 		&updated.ID, &updated.OrganizationID, &updated.Name, &updated.Sequence, &updated.Probability,
-		&updated.Fold, &updated.IsWon, &updated.Requirements, &updated.TeamID, &updated.CreatedAt, &updated.UpdatedAt,
+		&updated.Fold, &updated.IsWon, &updated.Requirements, &updated.TeamID, &updated.RottingDays, &updated.CreatedAt, &updated.UpdatedAt,
 	)
 
 	if err != nil {
@@ -153,10 +154,9 @@ func (r *leadStageRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // Count counts lead stages matching the filter criteria
 func (r *leadStageRepository) Count(ctx context.Context, filter types.LeadStageFilter) (int, error) {
-	// Get organization ID from context for security
-	orgID, ok := ctx.Value("organizationID").(uuid.UUID)
-	if !ok {
-		return 0, errors.New("organization ID not found in context")
+	orgID := filter.OrganizationID
+	if orgID == uuid.Nil {
+		return 0, errors.New("organization_id is required")
 	}
 
 	query := `SELECT COUNT(*) FROM lead_stages WHERE organization_id = $1`
@@ -164,8 +164,8 @@ func (r *leadStageRepository) Count(ctx context.Context, filter types.LeadStageF
 	argIndex := 2
 
 	if filter.Name != nil && *filter.Name != "" {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", argIndex)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
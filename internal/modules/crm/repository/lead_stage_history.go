@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type leadStageHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewLeadStageHistoryRepository(db *sql.DB) types.LeadStageHistoryRepository {
+	return &leadStageHistoryRepository{db: db}
+}
+
+// MoveStage locks the lead, checks it is still in the stage the change
+// moves it from, then updates its stage and records the change with the
+// time the lead spent in its previous stage. The stage probability, when
+// set, becomes the lead's system probability and, unless overridden, its
+// probability.
+func (r *leadStageHistoryRepository) MoveStage(ctx context.Context, change types.LeadStageChange, probability *int) (*types.LeadStageChange, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current uuid.NullUUID
+	var since sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT stage_id, COALESCE(date_last_stage_update, created_at)
+		FROM leads
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+		FOR UPDATE`,
+		change.LeadID, change.OrganizationID,
+	).Scan(&current, &since)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("lead not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to lock lead: %w", err)
+	}
+
+	if current.Valid != (change.FromStageID != nil) || (current.Valid && current.UUID != *change.FromStageID) {
+		return nil, types.ErrLeadStageChanged
+	}
+
+	if since.Valid && change.ChangedAt.After(since.Time) {
+		change.DurationSeconds = int64(change.ChangedAt.Sub(since.Time).Seconds())
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE leads SET
+			stage_id = $1,
+			system_probability = COALESCE($2, system_probability),
+			probability = CASE WHEN probability_overridden THEN probability ELSE COALESCE($2, probability) END,
+			date_last_stage_update = $3,
+			updated_at = $3,
+			updated_by = $4,
+			version = version + 1
+		WHERE id = $5`,
+		change.ToStageID, probability, change.ChangedAt, change.ChangedBy, change.LeadID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lead stage: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO lead_stage_history (
+			id, organization_id, lead_id, team_id, from_stage_id, to_stage_id,
+			changed_by, changed_at, duration_seconds, note
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		change.ID, change.OrganizationID, change.LeadID, change.TeamID, change.FromStageID, change.ToStageID,
+		change.ChangedBy, change.ChangedAt, change.DurationSeconds, change.Note,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record lead stage change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead stage change: %w", err)
+	}
+
+	return &change, nil
+}
+
+func (r *leadStageHistoryRepository) FindByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadStageChange, error) {
+	query := `
+		SELECT id, organization_id, lead_id, team_id, from_stage_id, to_stage_id,
+			changed_by, changed_at, duration_seconds, note
+		FROM lead_stage_history
+		WHERE organization_id = $1 AND lead_id = $2
+		ORDER BY changed_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead stage history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []types.LeadStageChange{}
+	for rows.Next() {
+		var change types.LeadStageChange
+		if err := rows.Scan(&change.ID, &change.OrganizationID, &change.LeadID, &change.TeamID,
+			&change.FromStageID, &change.ToStageID, &change.ChangedBy, &change.ChangedAt,
+			&change.DurationSeconds, &change.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan lead stage change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead stage history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// Velocity summarizes, per stage, the time leads spent in it before moving
+// on and the leads sitting in it now. Stages of other teams are left out
+// when filtering by team.
+func (r *leadStageHistoryRepository) Velocity(ctx context.Context, filter types.StageVelocityFilter) ([]types.StageVelocity, error) {
+	args := []interface{}{filter.OrganizationID}
+
+	exitsWhere := "h.organization_id = $1 AND h.from_stage_id IS NOT NULL"
+	currentWhere := "l.organization_id = $1 AND l.stage_id IS NOT NULL AND l.deleted_at IS NULL AND COALESCE(l.active, true)"
+	stagesWhere := "s.organization_id = $1"
+
+	if filter.TeamID != nil {
+		args = append(args, *filter.TeamID)
+		n := len(args)
+		exitsWhere += fmt.Sprintf(" AND h.team_id = $%d", n)
+		currentWhere += fmt.Sprintf(" AND l.team_id = $%d", n)
+		stagesWhere += fmt.Sprintf(" AND (s.team_id IS NULL OR s.team_id = $%d)", n)
+	}
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		exitsWhere += fmt.Sprintf(" AND h.changed_at >= $%d", len(args))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		exitsWhere += fmt.Sprintf(" AND h.changed_at < $%d", len(args))
+	}
+
+	query := `
+		WITH exits AS (
+			SELECT h.from_stage_id AS stage_id,
+				COUNT(*) AS exits,
+				AVG(h.duration_seconds)::float8 AS average_seconds,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY h.duration_seconds) AS median_seconds
+			FROM lead_stage_history h
+			WHERE ` + exitsWhere + `
+			GROUP BY h.from_stage_id
+		), current_leads AS (
+			SELECT l.stage_id,
+				COUNT(*) AS leads,
+				AVG(EXTRACT(EPOCH FROM (now() - COALESCE(l.date_last_stage_update, l.created_at))))::float8 AS age_seconds
+			FROM leads l
+			WHERE ` + currentWhere + `
+			GROUP BY l.stage_id
+		)
+		SELECT s.id, s.name, COALESCE(s.sequence, 0),
+			COALESCE(e.exits, 0), COALESCE(e.average_seconds, 0), COALESCE(e.median_seconds, 0),
+			COALESCE(c.leads, 0), COALESCE(c.age_seconds, 0)
+		FROM lead_stages s
+		LEFT JOIN exits e ON e.stage_id = s.id
+		LEFT JOIN current_leads c ON c.stage_id = s.id
+		WHERE ` + stagesWhere + `
+		ORDER BY s.sequence, s.name`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stage velocity: %w", err)
+	}
+	defer rows.Close()
+
+	const secondsPerDay = 24 * 60 * 60
+	velocity := []types.StageVelocity{}
+	for rows.Next() {
+		var v types.StageVelocity
+		var averageSeconds, medianSeconds, ageSeconds float64
+		if err := rows.Scan(&v.StageID, &v.StageName, &v.Sequence, &v.Exits, &averageSeconds,
+			&medianSeconds, &v.CurrentLeads, &ageSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan stage velocity: %w", err)
+		}
+		v.AverageDays = averageSeconds / secondsPerDay
+		v.MedianDays = medianSeconds / secondsPerDay
+		v.AverageAgeDays = ageSeconds / secondsPerDay
+		velocity = append(velocity, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stage velocity: %w", err)
+	}
+
+	return velocity, nil
+}
+
+func (r *leadStageHistoryRepository) FindTransitions(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.LeadStageTransitions, error) {
+	transitions, err := r.findTransitions(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if len(transitions.Transitions) == 0 && teamID != nil {
+		return r.findTransitions(ctx, orgID, nil)
+	}
+	return transitions, nil
+}
+
+func (r *leadStageHistoryRepository) findTransitions(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.LeadStageTransitions, error) {
+	query := `
+		SELECT from_stage_id, to_stage_id
+		FROM lead_stage_transitions
+		WHERE organization_id = $1 AND team_id IS NOT DISTINCT FROM $2
+		ORDER BY from_stage_id, to_stage_id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lead stage transitions: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := &types.LeadStageTransitions{TeamID: teamID, Transitions: []types.LeadStageTransition{}}
+	for rows.Next() {
+		var transition types.LeadStageTransition
+		if err := rows.Scan(&transition.FromStageID, &transition.ToStageID); err != nil {
+			return nil, fmt.Errorf("failed to scan lead stage transition: %w", err)
+		}
+		transitions.Transitions = append(transitions.Transitions, transition)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lead stage transitions: %w", err)
+	}
+
+	return transitions, nil
+}
+
+// ReplaceTransitions replaces every transition of the pipeline. An empty
+// list removes the pipeline's transitions, so it falls back to the
+// organization default, or allows every move for the default itself.
+func (r *leadStageHistoryRepository) ReplaceTransitions(ctx context.Context, orgID uuid.UUID, transitions types.LeadStageTransitions) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM lead_stage_transitions
+		WHERE organization_id = $1 AND team_id IS NOT DISTINCT FROM $2`,
+		orgID, transitions.TeamID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear lead stage transitions: %w", err)
+	}
+
+	for _, transition := range transitions.Transitions {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO lead_stage_transitions (id, organization_id, team_id, from_stage_id, to_stage_id)
+			VALUES ($1, $2, $3, $4, $5)`,
+			uuid.New(), orgID, transitions.TeamID, transition.FromStageID, transition.ToStageID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create lead stage transition: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lead stage transitions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func newStageChange(from, to uuid.UUID) types.LeadStageChange {
+	return types.LeadStageChange{
+		ID:             uuid.New(),
+		OrganizationID: uuid.New(),
+		LeadID:         uuid.New(),
+		FromStageID:    &from,
+		ToStageID:      &to,
+		ChangedAt:      time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestMoveStageRecordsTimeInPreviousStage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	from, to := uuid.New(), uuid.New()
+	change := newStageChange(from, to)
+	since := change.ChangedAt.Add(-50 * time.Hour)
+	probability := 40
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT stage_id").
+		WithArgs(change.LeadID, change.OrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"stage_id", "since"}).AddRow(from.String(), since))
+	// An overridden probability is kept; the stage one is recorded either way
+	mock.ExpectExec(`system_probability = COALESCE\(\$2, system_probability\),\s+`+
+		`probability = CASE WHEN probability_overridden THEN probability ELSE COALESCE\(\$2, probability\) END`).
+		WithArgs(change.ToStageID, 40, change.ChangedAt, change.ChangedBy, change.LeadID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO lead_stage_history").
+		WithArgs(change.ID, change.OrganizationID, change.LeadID, change.TeamID, change.FromStageID, change.ToStageID,
+			change.ChangedBy, change.ChangedAt, int64(50*60*60), change.Note).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	moved, err := NewLeadStageHistoryRepository(db).MoveStage(context.Background(), change, &probability)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50*60*60), moved.DurationSeconds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveStageRejectsConcurrentMove(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	change := newStageChange(uuid.New(), uuid.New())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT stage_id").
+		WithArgs(change.LeadID, change.OrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"stage_id", "since"}).AddRow(uuid.New().String(), change.ChangedAt))
+	mock.ExpectRollback()
+
+	_, err = NewLeadStageHistoryRepository(db).MoveStage(context.Background(), change, nil)
+	assert.ErrorIs(t, err, types.ErrLeadStageChanged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindTransitionsFallsBackToOrganizationDefault(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, teamID := uuid.New(), uuid.New()
+	from, to := uuid.New(), uuid.New()
+
+	mock.ExpectQuery("FROM lead_stage_transitions").
+		WithArgs(orgID, &teamID).
+		WillReturnRows(sqlmock.NewRows([]string{"from_stage_id", "to_stage_id"}))
+	mock.ExpectQuery("FROM lead_stage_transitions").
+		WithArgs(orgID, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"from_stage_id", "to_stage_id"}).AddRow(from.String(), to.String()))
+
+	transitions, err := NewLeadStageHistoryRepository(db).FindTransitions(context.Background(), orgID, &teamID)
+	require.NoError(t, err)
+	assert.Nil(t, transitions.TeamID)
+	assert.Equal(t, []types.LeadStageTransition{{FromStageID: from, ToStageID: to}}, transitions.Transitions)
+	assert.True(t, transitions.Allows(&from, to))
+	assert.False(t, transitions.Allows(&to, from))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
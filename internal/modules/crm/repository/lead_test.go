@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+// leadValueConverter passes lead arguments through as the driver would see
+// them, keeping slices such as tag_ids intact so they can be read back
+type leadValueConverter struct{}
+
+func (c leadValueConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil, nil
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	if rv.Kind() == reflect.Ptr {
+		return c.ConvertValue(rv.Elem().Interface())
+	}
+	return v, nil
+}
+
+// capturedArg matches any argument and keeps its value
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func leadColumnNames() []string {
+	var names []string
+	for _, column := range strings.Split(leadListColumns, ",") {
+		names = append(names, strings.TrimSpace(column))
+	}
+	return names
+}
+
+// TestLeadCreateThenRead creates a lead and reads it back from the row the
+// insert wrote, so a lead written by Create is exactly what every read returns
+func TestLeadCreateThenRead(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(leadValueConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewLeadRepository(db, nil)
+
+	stageID, userID := uuid.New(), uuid.New()
+	revenue := 12000.0
+	stageProbability := 25
+	deadline := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+	email := "buyer@acme.test"
+	lead := types.Lead{
+		OrganizationID:  uuid.New(),
+		Name:            "Acme expansion",
+		Email:           &email,
+		StageID:         &stageID,
+		AssignedTo:      &userID,
+		Priority:        types.LeadPriorityHigh,
+		ExpectedRevenue: &revenue,
+		DateDeadline:    &deadline,
+		TagIDs:          []uuid.UUID{uuid.New()},
+		CreatedAt:       time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:       time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	lead.ApplyStageProbability(&stageProbability)
+	lead.OverrideProbability(40)
+
+	columns := leadColumnNames()
+	written := make([]*capturedArg, len(columns))
+	args := make([]driver.Value, len(columns))
+	for i := range written {
+		written[i] = &capturedArg{}
+		args[i] = written[i]
+	}
+	mock.ExpectExec(`INSERT INTO leads \(`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	created, err := repo.Create(context.Background(), lead)
+	require.NoError(t, err)
+
+	row := make([]driver.Value, len(written))
+	for i, arg := range written {
+		row[i] = arg.value
+	}
+	mock.ExpectQuery(`FROM leads\s+WHERE id = \$1`).
+		WithArgs(created.ID).
+		WillReturnRows(mock.NewRows(columns).AddRow(row...))
+	mock.ExpectQuery(`FROM leads WHERE`).
+		WillReturnRows(mock.NewRows(columns).AddRow(row...))
+
+	read, err := repo.FindByID(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, read)
+
+	listed, err := repo.FindAll(context.Background(), types.LeadFilter{OrganizationID: lead.OrganizationID})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, created, listed[0])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLeadUpdateRefusesStaleVersion writes a lead only over the version it
+// was read at, answering the stored lead when another write came first
+func TestLeadUpdateRefusesStaleVersion(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(leadValueConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := NewLeadRepository(db, nil)
+	ctx := context.Background()
+
+	columns := leadColumnNames()
+	written := make([]*capturedArg, len(columns))
+	args := make([]driver.Value, len(columns))
+	for i := range written {
+		written[i] = &capturedArg{}
+		args[i] = written[i]
+	}
+	mock.ExpectExec(`INSERT INTO leads \(`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	created, err := repo.Create(ctx, types.Lead{OrganizationID: uuid.New(), Name: "Acme"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.Version)
+
+	mock.ExpectExec(`version = version \+ 1\s+WHERE id = \$45 AND deleted_at IS NULL AND version = \$46`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	updated, err := repo.Update(ctx, *created)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	// Another update wrote version 3 meanwhile
+	row := make([]driver.Value, len(written))
+	for i, arg := range written {
+		row[i] = arg.value
+	}
+	row[len(row)-1] = int64(3)
+	mock.ExpectExec(`AND version = \$46`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM leads\s+WHERE id = \$1`).
+		WithArgs(created.ID).
+		WillReturnRows(mock.NewRows(columns).AddRow(row...))
+
+	_, err = repo.Update(ctx, *updated)
+	assert.ErrorIs(t, err, types.ErrLeadVersionConflict)
+	var conflict *types.LeadVersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 3, conflict.Current.Version)
+	assert.Equal(t, "Acme", conflict.Current.Name)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLeadFindPageCountsInTheSameQuery reads the total from the window
+// column of the page, and counts apart only when the page is past the end
+func TestLeadFindPageCountsInTheSameQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(leadValueConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := &LeadRepository{db: db, dialect: database.Postgres}
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	// Take the row from what Create writes, so it scans like a real one
+	written := make([]*capturedArg, len(leadColumnNames()))
+	args := make([]driver.Value, len(written))
+	for i := range written {
+		written[i] = &capturedArg{}
+		args[i] = written[i]
+	}
+	mock.ExpectExec(`INSERT INTO leads \(`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = repo.Create(ctx, types.Lead{OrganizationID: orgID, Name: "Acme", LeadType: types.LeadTypeLead, Priority: types.LeadPriorityHigh, Status: types.LeadStatusNew})
+	require.NoError(t, err)
+	row := make([]driver.Value, len(written))
+	for i, arg := range written {
+		row[i] = arg.value
+	}
+
+	mock.ExpectQuery(`, COUNT\(\*\) OVER\(\) AS total FROM leads WHERE .* LIMIT 1`).
+		WillReturnRows(mock.NewRows(append(leadColumnNames(), "total")).AddRow(append(row, 42)...))
+
+	page, err := repo.FindPage(ctx, types.LeadFilter{OrganizationID: orgID, Limit: 1}, true)
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 42, *page.Total)
+	assert.Equal(t, 1, page.Limit)
+
+	mock.ExpectQuery(`COUNT\(\*\) OVER\(\)`).WillReturnRows(sqlmock.NewRows(append(leadColumnNames(), "total")))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM leads WHERE`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	page, err = repo.FindPage(ctx, types.LeadFilter{OrganizationID: orgID, Limit: 10, Offset: 100}, true)
+	require.NoError(t, err)
+	assert.Empty(t, page.Data)
+	assert.Equal(t, 42, *page.Total)
+
+	mock.ExpectQuery(`FROM leads WHERE`).WillReturnRows(sqlmock.NewRows(leadColumnNames()))
+
+	page, err = repo.FindPage(ctx, types.LeadFilter{OrganizationID: orgID}, false)
+	require.NoError(t, err)
+	assert.Nil(t, page.Total, "totals are off")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLeadFindByStatusFiltersOnStatus selects leads by their lifecycle status
+// column, which is no longer inferred from the active flag
+func TestLeadFindByStatusFiltersOnStatus(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(leadValueConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := &LeadRepository{db: db, dialect: database.Postgres}
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	written := make([]*capturedArg, len(leadColumnNames()))
+	args := make([]driver.Value, len(written))
+	for i := range written {
+		written[i] = &capturedArg{}
+		args[i] = written[i]
+	}
+	mock.ExpectExec(`INSERT INTO leads \(`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = repo.Create(ctx, types.Lead{OrganizationID: orgID, Name: "Acme", Active: true, Status: types.LeadStatusArchived})
+	require.NoError(t, err)
+	row := make([]driver.Value, len(written))
+	for i, arg := range written {
+		row[i] = arg.value
+	}
+
+	mock.ExpectQuery(`FROM leads\s+WHERE organization_id = \$1 AND status = \$2 AND deleted_at IS NULL\s+ORDER BY name ASC`).
+		WithArgs(orgID, types.LeadStatusArchived).
+		WillReturnRows(mock.NewRows(leadColumnNames()).AddRow(row...))
+
+	leads, err := repo.FindByStatus(ctx, orgID, types.LeadStatusArchived)
+	require.NoError(t, err)
+	require.Len(t, leads, 1)
+	assert.Equal(t, types.LeadStatusArchived, leads[0].Status)
+	assert.True(t, leads[0].Active, "an archived lead keeps its own active flag")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLeadRepositoryUsesOneTable runs every lead query and checks that each
+// reads or writes the leads table
+func TestLeadRepositoryUsesOneTable(t *testing.T) {
+	matcher := &recordingMatcher{}
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher), sqlmock.ValueConverterOption(leadValueConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := &LeadRepository{db: db, dialect: database.Postgres}
+
+	ctx := context.Background()
+	orgID, id := uuid.New(), uuid.New()
+	now := time.Now()
+	empty := func() { mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows(leadColumnNames())) }
+	updated := func() { mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, 1)) }
+
+	calls := []func(){
+		func() { updated(); repo.Create(ctx, types.Lead{OrganizationID: orgID, Name: "Lead"}) },
+		func() { empty(); repo.FindByID(ctx, id) },
+		func() { empty(); repo.FindAll(ctx, types.LeadFilter{OrganizationID: orgID}) },
+		func() {
+			mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows(append(leadColumnNames(), "total")))
+			repo.FindPage(ctx, types.LeadFilter{OrganizationID: orgID}, true)
+		},
+		func() { empty(); repo.FindByStatus(ctx, orgID, types.LeadStatusNew) },
+		func() { empty(); repo.FindByPriority(ctx, orgID, types.LeadPriorityHigh) },
+		func() { empty(); repo.FindByType(ctx, orgID, types.LeadTypeLead) },
+		func() { empty(); repo.FindByWonStatus(ctx, orgID, types.LeadWonStatusWon) },
+		func() { empty(); repo.FindOverdue(ctx, orgID) },
+		func() { empty(); repo.FindHighValue(ctx, orgID, 1000) },
+		func() { empty(); repo.FindBySearchTerm(ctx, orgID, "acme") },
+		func() { empty(); repo.FindOpenByEmailSince(ctx, orgID, "a@b.test", now) },
+		func() { updated(); repo.Update(ctx, types.Lead{ID: id, OrganizationID: orgID, Name: "Lead"}) },
+		func() { updated(); repo.Delete(ctx, id) },
+		func() {
+			mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			repo.Count(ctx, types.LeadFilter{OrganizationID: orgID})
+		},
+		func() { empty(); repo.FindByContact(ctx, orgID, id) },
+		func() { empty(); repo.FindByUser(ctx, orgID, id) },
+		func() { empty(); repo.FindByTeam(ctx, orgID, id) },
+		func() { empty(); repo.FindByStage(ctx, orgID, id) },
+		func() {
+			mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"stage_id", "count"}))
+			repo.CountByStage(ctx, orgID)
+		},
+		func() { empty(); repo.FindByDateRange(ctx, orgID, now, now) },
+		func() { empty(); repo.FindByDeadlineRange(ctx, orgID, now, now) },
+	}
+	for _, call := range calls {
+		call()
+	}
+
+	require.Len(t, matcher.queries, len(calls))
+	table := regexp.MustCompile(`\b(FROM|INTO|UPDATE) leads\b`)
+	for _, query := range matcher.queries {
+		assert.Regexp(t, table, query)
+		assert.NotContains(t, query, "leads_enhanced")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestLeadQueriesPerDialect renders lead listing and search for each dialect
+// and checks the SQL uses only what that database understands
+func TestLeadQueriesPerDialect(t *testing.T) {
+	postgresOnly := regexp.MustCompile(`ts_rank_cd|ts_headline|GROUPING`)
+	name := "acme"
+	filter := types.LeadFilter{OrganizationID: uuid.New(), Name: &name, Limit: 20}
+	search := types.LeadSearchRequest{OrganizationID: filter.OrganizationID, Query: "acme", Limit: 20}
+
+	tests := []struct {
+		dialect database.Dialect
+		search  bool
+		check   func(t *testing.T, query string)
+	}{
+		{database.Postgres, true, func(t *testing.T, query string) {}},
+		{database.CockroachDB, true, func(t *testing.T, query string) {
+			assert.NotRegexp(t, postgresOnly, query)
+		}},
+		{database.MySQL, false, func(t *testing.T, query string) {
+			assert.NotContains(t, query, "$")
+			assert.NotContains(t, query, "ILIKE")
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dialect.Name(), func(t *testing.T) {
+			matcher := &recordingMatcher{}
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+			require.NoError(t, err)
+			defer db.Close()
+			ctx := context.Background()
+
+			mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows(leadColumnNames()))
+			mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			repo := NewLeadRepository(db, test.dialect)
+			_, err = repo.FindAll(ctx, filter)
+			require.NoError(t, err)
+			_, err = repo.Count(ctx, filter)
+			require.NoError(t, err)
+
+			if test.search {
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows(leadColumnNames()))
+				_, err = repo.FindBySearchTerm(ctx, filter.OrganizationID, "acme")
+				require.NoError(t, err)
+
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "description", "rank"}))
+				mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"stage_id", "name", "priority", "by_stage", "count"}))
+				_, err = NewLeadSearchRepository(db, test.dialect).Search(ctx, search)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+			for _, query := range matcher.queries {
+				test.check(t, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+
+	"github.com/google/uuid"
+)
+
+type leadTrashRepository struct {
+	db *sql.DB
+}
+
+func NewLeadTrashRepository(db *sql.DB) types.LeadTrashRepository {
+	return &leadTrashRepository{db: db}
+}
+
+// ListDeleted counts the deleted leads with a window over the page. A page
+// past the last lead has no row to carry the total, which is then counted
+// apart.
+func (r *leadTrashRepository) ListDeleted(ctx context.Context, orgID uuid.UUID, limit, offset int) (*types.LeadPage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+leadListColumns+`, COUNT(*) OVER ()
+		FROM leads
+		WHERE organization_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+		LIMIT $2 OFFSET $3`,
+		orgID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted leads: %w", err)
+	}
+	defer rows.Close()
+
+	page := &types.LeadPage{Data: []*types.Lead{}, Limit: limit, Offset: offset}
+	var total int
+	for rows.Next() {
+		var lead types.Lead
+		if err := rows.Scan(append(leadListDest(&lead), &total)...); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted lead: %w", err)
+		}
+		page.Data = append(page.Data, &lead)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted leads: %w", err)
+	}
+
+	if len(page.Data) == 0 && offset > 0 {
+		err := r.db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM leads
+			WHERE organization_id = $1 AND deleted_at IS NOT NULL`,
+			orgID,
+		).Scan(&total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count deleted leads: %w", err)
+		}
+	}
+	page.Total = &total
+	return page, nil
+}
+
+func (r *leadTrashRepository) Restore(ctx context.Context, orgID, id uuid.UUID, restoredBy *uuid.UUID, restoredAt time.Time) (*types.Lead, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT deleted_at FROM leads
+		WHERE organization_id = $1 AND id = $2 AND deleted_at IS NOT NULL
+		FOR UPDATE`,
+		orgID, id,
+	).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("deleted lead not found or access denied")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted lead: %w", err)
+	}
+
+	var lead types.Lead
+	err = tx.QueryRowContext(ctx, `
+		UPDATE leads SET deleted_at = NULL, updated_at = $3, updated_by = $4, version = version + 1
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+leadListColumns,
+		orgID, id, restoredAt, restoredBy,
+	).Scan(leadListDest(&lead)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore lead: %w", err)
+	}
+
+	change := audit.FieldChange{Field: "deleted_at", Old: deletedAt, New: nil}
+	entries, err := types.NewLeadAuditEntries(orgID, id, []audit.FieldChange{change}, types.LeadAuditRestore, restoredBy, restoredAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertLeadAuditEntries(ctx, tx, entries); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit lead restore: %w", err)
+	}
+
+	return &lead, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeadTrashListCountsPastLastPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID := uuid.New()
+	mock.ExpectQuery(`COUNT\(\*\) OVER \(\)\s+FROM leads\s+WHERE organization_id = \$1 AND deleted_at IS NOT NULL\s+ORDER BY deleted_at DESC`).
+		WithArgs(orgID, 50, 100).
+		WillReturnRows(sqlmock.NewRows(append(leadColumnNames(), "total")))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM leads\s+WHERE organization_id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	page, err := NewLeadTrashRepository(db).ListDeleted(context.Background(), orgID, 50, 100)
+	require.NoError(t, err)
+	assert.NotNil(t, page.Data)
+	assert.Empty(t, page.Data)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 7, *page.Total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeadTrashRestoreOnlyDeletedLeads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, leadID := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT deleted_at FROM leads\s+WHERE organization_id = \$1 AND id = \$2 AND deleted_at IS NOT NULL\s+FOR UPDATE`).
+		WithArgs(orgID, leadID).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}))
+	mock.ExpectRollback()
+
+	_, err = NewLeadTrashRepository(db).Restore(context.Background(), orgID, leadID, nil, time.Now())
+	assert.EqualError(t, err, "deleted lead not found or access denied")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
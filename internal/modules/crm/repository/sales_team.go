@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -71,8 +72,8 @@ func (r *salesTeamRepository) FindAll(ctx context.Context, filter types.SalesTea
 	}
 
 	if filter.Name != nil {
-		query += " AND name LIKE $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", len(args)+1)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 	}
 
 	if filter.Code != nil {
@@ -126,10 +127,9 @@ func (r *salesTeamRepository) FindAll(ctx context.Context, filter types.SalesTea
 
 // Count counts sales teams matching the filter criteria
 func (r *salesTeamRepository) Count(ctx context.Context, filter types.SalesTeamFilter) (int, error) {
-	// Get organization ID from context for security
-	orgID, ok := ctx.Value("organizationID").(uuid.UUID)
-	if !ok {
-		return 0, errors.New("organization ID not found in context")
+	orgID := filter.OrganizationID
+	if orgID == uuid.Nil {
+		return 0, errors.New("organization_id is required")
 	}
 
 	query := `SELECT COUNT(*) FROM sales_teams WHERE organization_id = $1`
@@ -137,8 +137,8 @@ func (r *salesTeamRepository) Count(ctx context.Context, filter types.SalesTeamF
 	argIndex := 2
 
 	if filter.Name != nil && *filter.Name != "" {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Name+"%")
+		query += " AND " + database.ILike("name", argIndex)
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type schedulingLinkRepository struct {
+	db *sql.DB
+}
+
+func NewSchedulingLinkRepository(db *sql.DB) types.SchedulingLinkRepository {
+	return &schedulingLinkRepository{db: db}
+}
+
+const schedulingLinkColumns = `id, organization_id, name, token, user_id, team_id, duration_minutes, step_minutes,
+	buffer_minutes, min_notice_minutes, horizon_days, source_id, active, created_at, updated_at, created_by, updated_by`
+
+const leadBookingColumns = `id, organization_id, link_id, lead_id, host_user_id, event_id, activity_id,
+	starts_at, ends_at, invitee_name, invitee_email, notes, attribution, created_at`
+
+func scanSchedulingLink(row rowScanner) (*types.SchedulingLink, error) {
+	var link types.SchedulingLink
+	err := row.Scan(&link.ID, &link.OrganizationID, &link.Name, &link.Token, &link.UserID, &link.TeamID,
+		&link.DurationMinutes, &link.StepMinutes, &link.BufferMinutes, &link.MinNoticeMinutes, &link.HorizonDays,
+		&link.SourceID, &link.Active, &link.CreatedAt, &link.UpdatedAt, &link.CreatedBy, &link.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func scanLeadBooking(row rowScanner) (*types.LeadBooking, error) {
+	var booking types.LeadBooking
+	err := row.Scan(&booking.ID, &booking.OrganizationID, &booking.LinkID, &booking.LeadID, &booking.HostUserID,
+		&booking.EventID, &booking.ActivityID, &booking.StartsAt, &booking.EndsAt, &booking.InviteeName,
+		&booking.InviteeEmail, &booking.Notes, &booking.Attribution, &booking.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (r *schedulingLinkRepository) CreateLink(ctx context.Context, link types.SchedulingLink) (*types.SchedulingLink, error) {
+	query := `
+		INSERT INTO scheduling_links (
+			id, organization_id, name, token, user_id, team_id, duration_minutes, step_minutes,
+			buffer_minutes, min_notice_minutes, horizon_days, source_id, active, created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING ` + schedulingLinkColumns
+
+	created, err := scanSchedulingLink(r.db.QueryRowContext(ctx, query,
+		link.ID, link.OrganizationID, link.Name, link.Token, link.UserID, link.TeamID, link.DurationMinutes, link.StepMinutes,
+		link.BufferMinutes, link.MinNoticeMinutes, link.HorizonDays, link.SourceID, link.Active,
+		link.CreatedAt, link.UpdatedAt, link.CreatedBy, link.UpdatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduling link: %w", err)
+	}
+	return created, nil
+}
+
+func (r *schedulingLinkRepository) FindLink(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.SchedulingLink, error) {
+	query := `SELECT ` + schedulingLinkColumns + ` FROM scheduling_links WHERE id = $1 AND organization_id = $2`
+
+	link, err := scanSchedulingLink(r.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("scheduling link not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to find scheduling link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *schedulingLinkRepository) FindLinkByToken(ctx context.Context, token string) (*types.SchedulingLink, error) {
+	query := `SELECT ` + schedulingLinkColumns + ` FROM scheduling_links WHERE token = $1 AND active`
+
+	link, err := scanSchedulingLink(r.db.QueryRowContext(ctx, query, token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrSchedulingLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to find scheduling link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *schedulingLinkRepository) ListLinks(ctx context.Context, orgID uuid.UUID) ([]types.SchedulingLink, error) {
+	query := `SELECT ` + schedulingLinkColumns + ` FROM scheduling_links WHERE organization_id = $1 ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduling links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []types.SchedulingLink{}
+	for rows.Next() {
+		link, err := scanSchedulingLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduling link: %w", err)
+		}
+		links = append(links, *link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scheduling links: %w", err)
+	}
+
+	return links, nil
+}
+
+func (r *schedulingLinkRepository) UpdateLink(ctx context.Context, link types.SchedulingLink) (*types.SchedulingLink, error) {
+	query := `
+		UPDATE scheduling_links SET
+			name = $1, token = $2, user_id = $3, team_id = $4, duration_minutes = $5, step_minutes = $6,
+			buffer_minutes = $7, min_notice_minutes = $8, horizon_days = $9, source_id = $10, active = $11,
+			updated_at = $12, updated_by = $13
+		WHERE id = $14 AND organization_id = $15
+		RETURNING ` + schedulingLinkColumns
+
+	updated, err := scanSchedulingLink(r.db.QueryRowContext(ctx, query,
+		link.Name, link.Token, link.UserID, link.TeamID, link.DurationMinutes, link.StepMinutes,
+		link.BufferMinutes, link.MinNoticeMinutes, link.HorizonDays, link.SourceID, link.Active,
+		link.UpdatedAt, link.UpdatedBy, link.ID, link.OrganizationID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("scheduling link not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to update scheduling link: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *schedulingLinkRepository) DeleteLink(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM scheduling_links WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduling link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("scheduling link not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *schedulingLinkRepository) CreateBooking(ctx context.Context, booking types.LeadBooking, activity types.Activity, buffer time.Duration) (*types.LeadBooking, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Bookings of the same host wait for each other so that two prospects
+	// cannot both take the host's last free slot
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, booking.HostUserID.String()); err != nil {
+		return nil, fmt.Errorf("failed to lock host calendar: %w", err)
+	}
+
+	var busy bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM calendar_events
+			WHERE organization_id = $1 AND user_id = $2 AND status <> 'cancelled'
+				AND starts_at < $4 AND ends_at > $3
+		)`,
+		booking.OrganizationID, booking.HostUserID, booking.StartsAt.Add(-buffer), booking.EndsAt.Add(buffer),
+	).Scan(&busy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check host calendar: %w", err)
+	}
+	if busy {
+		return nil, types.ErrSlotUnavailable
+	}
+
+	eventID := uuid.New()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO calendar_events (
+			id, organization_id, user_id, title, description, starts_at, ends_at, status,
+			res_model, res_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, 'confirmed', 'leads', $8, $9, $9)`,
+		eventID, booking.OrganizationID, booking.HostUserID, activity.Summary, booking.Notes,
+		booking.StartsAt, booking.EndsAt, booking.LeadID, booking.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking calendar event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO activities (
+			id, organization_id, activity_type, summary, note, date_deadline, user_id, assigned_to,
+			res_model, res_id, state, done_date, created_at, updated_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		activity.ID, activity.OrganizationID, activity.ActivityType, activity.Summary, activity.Note,
+		activity.DateDeadline, activity.UserID, activity.AssignedTo, activity.ResModel, activity.ResID,
+		activity.State, activity.DoneDate, activity.CreatedAt, activity.UpdatedAt, activity.CreatedBy, activity.UpdatedBy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking activity: %w", err)
+	}
+
+	query := `
+		INSERT INTO lead_bookings (
+			id, organization_id, link_id, lead_id, host_user_id, event_id, activity_id,
+			starts_at, ends_at, invitee_name, invitee_email, notes, attribution, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + leadBookingColumns
+
+	created, err := scanLeadBooking(tx.QueryRowContext(ctx, query,
+		booking.ID, booking.OrganizationID, booking.LinkID, booking.LeadID, booking.HostUserID, eventID, activity.ID,
+		booking.StartsAt, booking.EndsAt, booking.InviteeName, booking.InviteeEmail, booking.Notes,
+		booking.Attribution, booking.CreatedAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit booking: %w", err)
+	}
+	return created, nil
+}
+
+func (r *schedulingLinkRepository) BookingCounts(ctx context.Context, linkID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT host_user_id, COUNT(*) FROM lead_bookings WHERE link_id = $1 GROUP BY host_user_id`, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var hostID uuid.UUID
+		var count int
+		if err := rows.Scan(&hostID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan booking count: %w", err)
+		}
+		counts[hostID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating booking counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *schedulingLinkRepository) ListBookings(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadBooking, error) {
+	query := `
+		SELECT ` + leadBookingColumns + `
+		FROM lead_bookings
+		WHERE organization_id = $1 AND lead_id = $2
+		ORDER BY starts_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := []types.LeadBooking{}
+	for rows.Next() {
+		booking, err := scanLeadBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, *booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %w", err)
+	}
+
+	return bookings, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestCreateBookingRejectsBusyHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	starts := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	booking := types.LeadBooking{
+		ID:             uuid.New(),
+		OrganizationID: uuid.New(),
+		LeadID:         uuid.New(),
+		HostUserID:     uuid.New(),
+		StartsAt:       starts,
+		EndsAt:         starts.Add(30 * time.Minute),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+		WithArgs(booking.HostUserID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// The 15 minute buffer widens the checked period on both sides
+	mock.ExpectQuery("FROM calendar_events").
+		WithArgs(booking.OrganizationID, booking.HostUserID, starts.Add(-15*time.Minute), starts.Add(45*time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = NewSchedulingLinkRepository(db).CreateBooking(context.Background(), booking, types.Activity{ID: uuid.New()}, 15*time.Minute)
+	assert.ErrorIs(t, err, types.ErrSlotUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateBookingAddsEventAndActivity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	starts := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	orgID, linkID, leadID, hostID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	booking := types.LeadBooking{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		LinkID:         &linkID,
+		LeadID:         leadID,
+		HostUserID:     hostID,
+		StartsAt:       starts,
+		EndsAt:         starts.Add(30 * time.Minute),
+		InviteeName:    "Ada",
+		InviteeEmail:   "ada@example.com",
+		Attribution:    types.LeadBookingEmail,
+		CreatedAt:      starts.Add(-48 * time.Hour),
+	}
+	activity := types.Activity{ID: uuid.New(), OrganizationID: orgID, ActivityType: types.ActivityTypeMeeting, Summary: "Meeting with Ada"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM calendar_events").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO calendar_events").
+		WithArgs(sqlmock.AnyArg(), orgID, hostID, "Meeting with Ada", nil, starts, booking.EndsAt, leadID, booking.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO activities").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO lead_bookings").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "link_id", "lead_id", "host_user_id", "event_id",
+			"activity_id", "starts_at", "ends_at", "invitee_name", "invitee_email", "notes", "attribution", "created_at"}).
+			AddRow(booking.ID.String(), orgID.String(), linkID.String(), leadID.String(), hostID.String(), uuid.NewString(),
+				activity.ID.String(), starts, booking.EndsAt, "Ada", "ada@example.com", nil, "email", booking.CreatedAt))
+	mock.ExpectCommit()
+
+	created, err := NewSchedulingLinkRepository(db).CreateBooking(context.Background(), booking, activity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, hostID, created.HostUserID)
+	assert.Equal(t, &activity.ID, created.ActivityID)
+	assert.NotNil(t, created.EventID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
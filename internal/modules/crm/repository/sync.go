@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+type syncRepository struct {
+	db *sql.DB
+}
+
+func NewSyncRepository(db *sql.DB) types.SyncRepository {
+	return &syncRepository{db: db}
+}
+
+// syncSources select each entity's written records, soft-deleted ones
+// flagged as deleted. Hard deletes are found in crm_sync_tombstones.
+var syncSources = map[types.SyncEntity]string{
+	types.SyncLeads:      `SELECT id, updated_at AS changed_at, deleted_at IS NOT NULL AS deleted FROM leads WHERE organization_id = $1`,
+	types.SyncContacts:   `SELECT id, updated_at AS changed_at, deleted_at IS NOT NULL AS deleted FROM contacts WHERE organization_id = $1`,
+	types.SyncActivities: `SELECT id, updated_at AS changed_at, false AS deleted FROM activities WHERE organization_id = $1`,
+}
+
+func (r *syncRepository) Changes(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, after types.SyncMarker, until time.Time, limit int) ([]types.SyncMarker, error) {
+	source, ok := syncSources[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown entity %q", types.ErrInvalidSync, entity)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, changed_at, deleted FROM (
+			`+source+`
+			UNION ALL
+			SELECT record_id, deleted_at, true FROM crm_sync_tombstones WHERE organization_id = $1 AND entity = $2
+		) changes
+		WHERE (changed_at, id) > ($3, $4) AND changed_at <= $5
+		ORDER BY changed_at, id
+		LIMIT $6
+	`, orgID, entity, after.ChangedAt, after.ID, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s changes: %w", entity, err)
+	}
+	defer rows.Close()
+
+	markers := []types.SyncMarker{}
+	for rows.Next() {
+		var marker types.SyncMarker
+		if err := rows.Scan(&marker.ID, &marker.ChangedAt, &marker.Deleted); err != nil {
+			return nil, fmt.Errorf("failed to scan %s change: %w", entity, err)
+		}
+		markers = append(markers, marker)
+	}
+	return markers, rows.Err()
+}
+
+func (r *syncRepository) FindRecord(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, id uuid.UUID) (*types.SyncMarker, error) {
+	source, ok := syncSources[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown entity %q", types.ErrInvalidSync, entity)
+	}
+
+	var marker types.SyncMarker
+	err := r.db.QueryRowContext(ctx, `SELECT id, changed_at, deleted FROM (`+source+`) records WHERE id = $2`, orgID, id).
+		Scan(&marker.ID, &marker.ChangedAt, &marker.Deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s record: %w", entity, err)
+	}
+	return &marker, nil
+}
+
+func (r *syncRepository) FindAppliedOp(ctx context.Context, orgID, clientID uuid.UUID) (*types.SyncAppliedOp, error) {
+	var op types.SyncAppliedOp
+	err := r.db.QueryRowContext(ctx, `
+		SELECT organization_id, client_id, user_id, entity, action, record_id, applied_at
+		FROM crm_sync_ops WHERE organization_id = $1 AND client_id = $2
+	`, orgID, clientID).Scan(&op.OrganizationID, &op.ClientID, &op.UserID, &op.Entity, &op.Action, &op.RecordID, &op.AppliedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sync operation: %w", err)
+	}
+	return &op, nil
+}
+
+func (r *syncRepository) RecordAppliedOp(ctx context.Context, op types.SyncAppliedOp) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO crm_sync_ops (organization_id, client_id, user_id, entity, action, record_id, applied_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (organization_id, client_id) DO NOTHING
+	`, op.OrganizationID, op.ClientID, op.UserID, op.Entity, op.Action, op.RecordID, op.AppliedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record sync operation: %w", err)
+	}
+	return nil
+}
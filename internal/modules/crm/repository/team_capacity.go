@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type teamCapacityRepository struct {
+	db *sql.DB
+}
+
+func NewTeamCapacityRepository(db *sql.DB) types.TeamCapacityRepository {
+	return &teamCapacityRepository{db: db}
+}
+
+func (r *teamCapacityRepository) ListCapacities(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserCapacity, error) {
+	query := `
+		SELECT ou.user_id, COALESCE(u.name, ''), COALESCE(u.email, ''),
+		       COALESCE(l.active_assignments, 0), COALESCE(l.total_assignments, 0), l.last_assigned_at,
+		       COALESCE(l.max_capacity, 0), COALESCE(l.weight, 1), COALESCE(l.is_available, true),
+		       l.unavailable_until
+		FROM organization_users ou
+		LEFT JOIN users u ON u.id = ou.user_id
+		LEFT JOIN user_assignment_load l
+			ON l.user_id = ou.user_id AND l.organization_id = ou.organization_id AND l.target_model = $2
+		WHERE ou.organization_id = $1 AND ou.is_active = true`
+	args := []interface{}{orgID, targetModel}
+	if userIDs != nil {
+		query += ` AND ou.user_id = ANY($3)`
+		args = append(args, pq.Array(userIDs))
+	}
+	query += ` ORDER BY u.name, ou.user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user capacities: %w", err)
+	}
+	defer rows.Close()
+
+	capacities := []types.UserCapacity{}
+	for rows.Next() {
+		capacity := types.UserCapacity{TargetModel: targetModel}
+		if err := rows.Scan(&capacity.UserID, &capacity.Name, &capacity.Email,
+			&capacity.ActiveAssignments, &capacity.TotalAssignments, &capacity.LastAssignedAt,
+			&capacity.MaxCapacity, &capacity.Weight, &capacity.IsAvailable, &capacity.UnavailableUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan user capacity: %w", err)
+		}
+		capacities = append(capacities, capacity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user capacities: %w", err)
+	}
+	return capacities, nil
+}
+
+func (r *teamCapacityRepository) SaveCapacity(ctx context.Context, orgID uuid.UUID, capacity types.UserCapacity) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_assignment_load (
+			organization_id, user_id, target_model, max_capacity, weight, is_available, unavailable_until
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (organization_id, user_id, target_model)
+		DO UPDATE SET
+			max_capacity = EXCLUDED.max_capacity,
+			weight = EXCLUDED.weight,
+			is_available = EXCLUDED.is_available,
+			unavailable_until = EXCLUDED.unavailable_until,
+			updated_at = CURRENT_TIMESTAMP
+	`, orgID, capacity.UserID, capacity.TargetModel, capacity.MaxCapacity, capacity.Weight,
+		capacity.IsAvailable, capacity.UnavailableUntil)
+	if err != nil {
+		return fmt.Errorf("failed to save user capacity: %w", err)
+	}
+	return nil
+}
+
+const userUnavailabilityColumns = `id, organization_id, user_id, starts_at, ends_at, reason, created_at, created_by`
+
+func scanUserUnavailability(row rowScanner) (*types.UserUnavailability, error) {
+	var period types.UserUnavailability
+	err := row.Scan(&period.ID, &period.OrganizationID, &period.UserID, &period.StartsAt, &period.EndsAt,
+		&period.Reason, &period.CreatedAt, &period.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (r *teamCapacityRepository) CreateUnavailability(ctx context.Context, period types.UserUnavailability) (*types.UserUnavailability, error) {
+	created, err := scanUserUnavailability(r.db.QueryRowContext(ctx, `
+		INSERT INTO user_unavailability (organization_id, user_id, starts_at, ends_at, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+userUnavailabilityColumns,
+		period.OrganizationID, period.UserID, period.StartsAt, period.EndsAt, period.Reason, period.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user unavailability: %w", err)
+	}
+	return created, nil
+}
+
+func (r *teamCapacityRepository) ListUnavailability(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, endingAfter time.Time) ([]types.UserUnavailability, error) {
+	query := `
+		SELECT ` + userUnavailabilityColumns + `
+		FROM user_unavailability
+		WHERE organization_id = $1 AND ends_at > $2`
+	args := []interface{}{orgID, endingAfter}
+	if userIDs != nil {
+		query += ` AND user_id = ANY($3)`
+		args = append(args, pq.Array(userIDs))
+	}
+	query += ` ORDER BY starts_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user unavailability: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []types.UserUnavailability{}
+	for rows.Next() {
+		period, err := scanUserUnavailability(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user unavailability: %w", err)
+		}
+		periods = append(periods, *period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user unavailability: %w", err)
+	}
+	return periods, nil
+}
+
+func (r *teamCapacityRepository) DeleteUnavailability(ctx context.Context, orgID, userID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM user_unavailability
+		WHERE id = $1 AND organization_id = $2 AND user_id = $3`,
+		id, orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete user unavailability: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete user unavailability: %w", err)
+	} else if n == 0 {
+		return types.ErrUserUnavailabilityNotFound
+	}
+	return nil
+}
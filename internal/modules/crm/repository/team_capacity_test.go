@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestListCapacitiesDefaultsMembersWithoutLoad(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, loaded, idle := uuid.New(), uuid.New(), uuid.New()
+	assigned := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`FROM organization_users ou\s+LEFT JOIN users u .+ AND ou.user_id = ANY\(\$3\)`).
+		WithArgs(orgID, "leads", pq.Array([]uuid.UUID{loaded, idle})).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "name", "email", "active", "total", "last_assigned_at",
+			"max_capacity", "weight", "is_available", "unavailable_until"}).
+			AddRow(loaded.String(), "Ada", "ada@example.com", 4, 30, assigned, 10, 2, true, nil).
+			AddRow(idle.String(), "Bo", "bo@example.com", 0, 0, nil, 0, 1, true, nil))
+
+	capacities, err := NewTeamCapacityRepository(db).ListCapacities(context.Background(), orgID, "leads", []uuid.UUID{loaded, idle})
+	require.NoError(t, err)
+	assert.Equal(t, []types.UserCapacity{
+		{UserID: loaded, Name: "Ada", Email: "ada@example.com", TargetModel: "leads", ActiveAssignments: 4,
+			TotalAssignments: 30, LastAssignedAt: &assigned, MaxCapacity: 10, Weight: 2, IsAvailable: true},
+		{UserID: idle, Name: "Bo", Email: "bo@example.com", TargetModel: "leads", Weight: 1, IsAvailable: true},
+	}, capacities)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUnavailabilityReportsMissingPeriod(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	orgID, userID, id := uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectExec(`DELETE FROM user_unavailability`).
+		WithArgs(id, orgID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewTeamCapacityRepository(db).DeleteUnavailability(context.Background(), orgID, userID, id)
+	assert.ErrorIs(t, err, types.ErrUserUnavailabilityNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
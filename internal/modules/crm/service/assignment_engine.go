@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+)
+
+// maxAssignmentAttempts bounds the retries of an assignment whose round robin
+// position was moved by a concurrent assignment
+const maxAssignmentAttempts = 3
+
+// AssignmentData is what strategies choose from: the loads of users and the
+// organization's territories
+type AssignmentData interface {
+	ListCandidateLoads(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error)
+	ListTerritories(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]*types.Territory, error)
+}
+
+// WorkingHoursResolver returns the working hours of the users who have
+// some, keyed by user
+type WorkingHoursResolver interface {
+	ResolveUsers(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*calendar.Calendar, error)
+}
+
+// AssignmentRequest is an assignment to choose a user for with a rule
+type AssignmentRequest struct {
+	Rule        *types.AssignmentRule
+	TargetModel string
+	// Attributes are the attributes of the record being assigned
+	Attributes map[string]interface{}
+	Now        time.Time
+}
+
+// AssignmentChoice is the user a strategy chose, uuid.Nil when no user is
+// eligible. Cursor is set by strategies that move the rule's round robin
+// position. Release returns the record to the pool instead of assigning it.
+// Spillover is set when nobody on shift could take the assignment and it
+// went to the rule's fallback users or the pool.
+type AssignmentChoice struct {
+	UserID    uuid.UUID
+	Cursor    *types.AssignmentCursor
+	Release   bool
+	Spillover bool
+}
+
+// AssignmentStrategy chooses the user a type of rule assigns. Strategies
+// only read data; the engine records their choices.
+type AssignmentStrategy interface {
+	Choose(ctx context.Context, req AssignmentRequest, data AssignmentData) (*AssignmentChoice, error)
+}
+
+// AssignmentEngine assigns records with the organization's assignment rules,
+// choosing the assignee with the strategy of the matching rule's type
+type AssignmentEngine struct {
+	repo       types.AssignmentEngineRepository
+	strategies map[types.AssignmentRuleType]AssignmentStrategy
+	hours      WorkingHoursResolver
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+// NewAssignmentEngine creates an engine with the round robin, weighted,
+// territory, custom and pool strategies
+func NewAssignmentEngine(repo types.AssignmentEngineRepository) *AssignmentEngine {
+	return &AssignmentEngine{
+		repo: repo,
+		strategies: map[types.AssignmentRuleType]AssignmentStrategy{
+			types.AssignmentRuleTypeRoundRobin: RoundRobinStrategy{},
+			types.AssignmentRuleTypeWeighted:   WeightedStrategy{},
+			types.AssignmentRuleTypeTerritory:  TerritoryStrategy{},
+			types.AssignmentRuleTypeCustom:     CustomStrategy{},
+			types.AssignmentRuleTypePool:       PoolStrategy{},
+		},
+		logger: slog.Default().With("service", "assignment-engine"),
+		now:    time.Now,
+	}
+}
+
+// RegisterStrategy sets the strategy of a rule type, replacing any other
+func (e *AssignmentEngine) RegisterStrategy(ruleType types.AssignmentRuleType, strategy AssignmentStrategy) {
+	e.strategies[ruleType] = strategy
+}
+
+// SetWorkingHours makes strategies pass over users who are off shift in
+// their own working hours. When that leaves a rule without anyone to
+// assign, the assignment spills over to the rule's fallback users, or to
+// the pool. Without working hours users are on shift around the clock.
+func (e *AssignmentEngine) SetWorkingHours(hours WorkingHoursResolver) {
+	e.hours = hours
+}
+
+// Choose finds the rule matching the attributes and the user it assigns,
+// without recording anything
+func (e *AssignmentEngine) Choose(ctx context.Context, orgID uuid.UUID, targetModel string, attributes map[string]interface{}) (*types.AssignmentRule, *AssignmentChoice, error) {
+	rule, err := e.repo.FindMatchingRule(ctx, orgID, targetModel, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strategy, ok := e.strategies[rule.RuleType]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no strategy for rule type %q", types.ErrInvalidAssignmentRule, rule.RuleType)
+	}
+	req := AssignmentRequest{
+		Rule:        rule,
+		TargetModel: targetModel,
+		Attributes:  attributes,
+		Now:         e.now(),
+	}
+	var data AssignmentData = e.repo
+	var shifts *onShiftData
+	if e.hours != nil {
+		shifts = &onShiftData{AssignmentData: e.repo, hours: e.hours, now: req.Now}
+		data = shifts
+	}
+	choice, err := strategy.Choose(ctx, req, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to choose %s assignee: %w", rule.RuleType, err)
+	}
+
+	if shifts != nil && shifts.offShift > 0 && choice.UserID == uuid.Nil && !choice.Release {
+		choice, err = e.spillover(ctx, req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to choose fallback assignee: %w", err)
+		}
+	}
+	return rule, choice, nil
+}
+
+// spillover sends an assignment nobody on shift could take to the least
+// loaded of the rule's fallback users, whatever their working hours, or to
+// the pool when none of them can take it
+func (e *AssignmentEngine) spillover(ctx context.Context, req AssignmentRequest) (*AssignmentChoice, error) {
+	if fallback := req.Rule.AssignmentConfig.FallbackUsers; len(fallback) > 0 {
+		loads, err := candidateLoads(ctx, req, e.repo, fallback)
+		if err != nil {
+			return nil, err
+		}
+		if userID := leastLoaded(loads, req.Rule); userID != uuid.Nil {
+			return &AssignmentChoice{UserID: userID, Spillover: true}, nil
+		}
+	}
+	return &AssignmentChoice{Release: true, Spillover: true}, nil
+}
+
+// onShiftData marks the users off shift at now as unavailable, counting
+// those who would otherwise have been available
+type onShiftData struct {
+	AssignmentData
+	hours    WorkingHoursResolver
+	now      time.Time
+	offShift int
+}
+
+func (d *onShiftData) ListCandidateLoads(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error) {
+	loads, err := d.AssignmentData.ListCandidateLoads(ctx, orgID, targetModel, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := d.hours.ResolveUsers(ctx, orgID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working hours: %w", err)
+	}
+	for i := range loads {
+		if cal, ok := calendars[loads[i].UserID]; ok && loads[i].IsAvailable && !cal.IsOpen(d.now) {
+			loads[i].IsAvailable = false
+			d.offShift++
+		}
+	}
+	return loads, nil
+}
+
+// AssignLead assigns a lead with the rule matching the conditions, or
+// returns it to the pool when the rule releases it. The assignment is chosen
+// again when a concurrent assignment moved the rule's round robin position
+// first.
+func (e *AssignmentEngine) AssignLead(ctx context.Context, lead *types.Lead, conditions map[string]interface{}) (*types.AssignmentResult, error) {
+	targetModel := string(types.AssignmentTargetModelLeads)
+	for attempt := 1; ; attempt++ {
+		rule, choice, err := e.Choose(ctx, lead.OrganizationID, targetModel, conditions)
+		if err != nil {
+			return nil, err
+		}
+		if choice.Release {
+			return e.releaseLead(ctx, rule, lead, choice.Spillover)
+		}
+		if choice.UserID == uuid.Nil {
+			return nil, fmt.Errorf("no suitable assignee found")
+		}
+
+		name, err := e.repo.GetUserName(ctx, choice.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get assignee name: %w", err)
+		}
+		result := &types.AssignmentResult{
+			LeadID:         lead.ID,
+			AssignedToID:   choice.UserID,
+			AssignedToName: name,
+		}
+
+		if lead.AssignedTo != nil && *lead.AssignedTo == choice.UserID {
+			result.Reason = "already_assigned"
+			return result, nil
+		}
+
+		result.Reason = "auto_assignment"
+		if lead.AssignedTo != nil {
+			result.Reason = "reassignment"
+		}
+		if choice.Spillover {
+			result.Reason = "off_shift_fallback"
+		}
+
+		err = e.repo.RecordAssignment(ctx, types.AssignmentRecord{
+			OrganizationID:       lead.OrganizationID,
+			RuleID:               rule.ID,
+			RuleName:             rule.Name,
+			TargetModel:          targetModel,
+			TargetID:             lead.ID,
+			AssignedToID:         choice.UserID,
+			PreviousAssignedToID: lead.AssignedTo,
+			Reason:               result.Reason,
+			Cursor:               choice.Cursor,
+		})
+		if errors.Is(err, types.ErrAssignmentConflict) && attempt < maxAssignmentAttempts {
+			e.logger.Info("Retrying assignment after a concurrent one", "rule_id", rule.ID, "lead_id", lead.ID)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign lead: %w", err)
+		}
+
+		result.Changed = true
+		return result, nil
+	}
+}
+
+// releaseLead returns a lead to the pool of unassigned leads, where it
+// waits for someone on shift when it spilled over
+func (e *AssignmentEngine) releaseLead(ctx context.Context, rule *types.AssignmentRule, lead *types.Lead, spillover bool) (*types.AssignmentResult, error) {
+	result := &types.AssignmentResult{LeadID: lead.ID, Reason: "already_in_pool"}
+	if spillover {
+		result.Reason = "off_shift_pool"
+	}
+	if lead.AssignedTo == nil {
+		return result, nil
+	}
+
+	if !spillover {
+		result.Reason = "returned_to_pool"
+	}
+	err := e.repo.RecordAssignment(ctx, types.AssignmentRecord{
+		OrganizationID:       lead.OrganizationID,
+		RuleID:               rule.ID,
+		RuleName:             rule.Name,
+		TargetModel:          string(types.AssignmentTargetModelLeads),
+		TargetID:             lead.ID,
+		PreviousAssignedToID: lead.AssignedTo,
+		Reason:               result.Reason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to return lead to the pool: %w", err)
+	}
+
+	result.Changed = true
+	return result, nil
+}
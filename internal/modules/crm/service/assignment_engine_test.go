@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+)
+
+// assignmentStore keeps a rule, loads and territories in memory
+type assignmentStore struct {
+	rule        *types.AssignmentRule
+	loads       map[uuid.UUID]types.UserAssignmentLoad
+	territories []*types.Territory
+	records     []types.AssignmentRecord
+	// conflicts fail as many recordings with ErrAssignmentConflict, as if
+	// another assignment moved the rule first
+	conflicts int
+}
+
+func (s *assignmentStore) FindMatchingRule(context.Context, uuid.UUID, string, map[string]interface{}) (*types.AssignmentRule, error) {
+	if s.rule == nil {
+		return nil, types.ErrNoMatchingAssignmentRule
+	}
+	rule := *s.rule
+	return &rule, nil
+}
+
+func (s *assignmentStore) ListCandidateLoads(_ context.Context, _ uuid.UUID, _ string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error) {
+	loads := make([]types.UserAssignmentLoad, len(userIDs))
+	for i, id := range userIDs {
+		load, ok := s.loads[id]
+		if !ok {
+			load = types.UserAssignmentLoad{Weight: 1, IsAvailable: true}
+		}
+		load.UserID = id
+		loads[i] = load
+	}
+	return loads, nil
+}
+
+func (s *assignmentStore) ListTerritories(context.Context, uuid.UUID, bool) ([]*types.Territory, error) {
+	return s.territories, nil
+}
+
+func (s *assignmentStore) GetUserName(context.Context, uuid.UUID) (string, error) {
+	return "Rep", nil
+}
+
+func (s *assignmentStore) RecordAssignment(_ context.Context, record types.AssignmentRecord) error {
+	if s.conflicts > 0 {
+		s.conflicts--
+		s.rule.AssignmentConfig.CurrentIndex++
+		return types.ErrAssignmentConflict
+	}
+	if record.Cursor != nil {
+		s.rule.AssignmentConfig.CurrentIndex = record.Cursor.To
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func choose(t *testing.T, strategy AssignmentStrategy, store *assignmentStore, attributes map[string]interface{}) *AssignmentChoice {
+	t.Helper()
+	choice, err := strategy.Choose(context.Background(), AssignmentRequest{
+		Rule: store.rule, TargetModel: "leads", Attributes: attributes, Now: time.Now(),
+	}, store)
+	require.NoError(t, err)
+	return choice
+}
+
+func TestRoundRobinSkipsUsersWhoCannotTakeLeads(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{MaxAssignmentsPerUser: 10, AssignmentConfig: types.AssignmentConfig{
+			Users: []uuid.UUID{a, b, c}, CurrentIndex: 1,
+		}},
+		loads: map[uuid.UUID]types.UserAssignmentLoad{
+			b: {IsAvailable: false},
+			c: {IsAvailable: true, ActiveAssignments: 10},
+		},
+	}
+
+	// b is away and c at the rule's maximum, so the turn wraps to a
+	choice := choose(t, RoundRobinStrategy{}, store, nil)
+	assert.Equal(t, a, choice.UserID)
+	assert.Equal(t, &types.AssignmentCursor{From: 1, To: 1}, choice.Cursor)
+
+	store.loads[b] = types.UserAssignmentLoad{IsAvailable: true}
+	choice = choose(t, RoundRobinStrategy{}, store, nil)
+	assert.Equal(t, b, choice.UserID)
+	assert.Equal(t, &types.AssignmentCursor{From: 1, To: 2}, choice.Cursor)
+
+	store.loads[a] = types.UserAssignmentLoad{IsAvailable: true, MaxCapacity: 2, ActiveAssignments: 2}
+	store.loads[b] = types.UserAssignmentLoad{IsAvailable: false}
+	assert.Equal(t, &AssignmentChoice{}, choose(t, RoundRobinStrategy{}, store, nil))
+}
+
+func TestWeightedAssignsFewestLeadsPerWeight(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{AssignmentConfig: types.AssignmentConfig{Assignments: []types.WeightedAssignment{
+			{UserID: a, Weight: 3}, {UserID: b, Weight: 1}, {UserID: c},
+		}}},
+		loads: map[uuid.UUID]types.UserAssignmentLoad{
+			a: {IsAvailable: true, ActiveAssignments: 6},
+			b: {IsAvailable: true, ActiveAssignments: 2},
+			c: {IsAvailable: false},
+		},
+	}
+
+	// a and b both carry 2 leads per weight; a is listed first
+	assert.Equal(t, a, choose(t, WeightedStrategy{}, store, nil).UserID)
+
+	store.loads[a] = types.UserAssignmentLoad{IsAvailable: true, ActiveAssignments: 7}
+	assert.Equal(t, b, choose(t, WeightedStrategy{}, store, nil).UserID)
+}
+
+func TestTerritoryAssignsLeastLoadedUserOfMatchingTerritory(t *testing.T) {
+	west, east, full, fallback := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{},
+		territories: []*types.Territory{
+			{Name: "Strategic", Conditions: map[string]interface{}{"country": "US", "segment": "enterprise"}, AssignedUsers: []uuid.UUID{full}},
+			{Name: "US West", Conditions: map[string]interface{}{"country": "US", "state": []interface{}{"CA", "OR", "WA"}}, AssignedUsers: []uuid.UUID{west, east}},
+			{Name: "Everywhere", Conditions: map[string]interface{}{}, AssignedUsers: []uuid.UUID{fallback}},
+		},
+		loads: map[uuid.UUID]types.UserAssignmentLoad{
+			full: {IsAvailable: true, MaxCapacity: 1, ActiveAssignments: 1},
+			west: {IsAvailable: true, ActiveAssignments: 3},
+			east: {IsAvailable: true, ActiveAssignments: 1},
+		},
+	}
+
+	assert.Equal(t, east, choose(t, TerritoryStrategy{}, store, map[string]interface{}{"country": "US", "state": "OR"}).UserID)
+	// The full strategic territory falls through to US West
+	assert.Equal(t, east, choose(t, TerritoryStrategy{}, store, map[string]interface{}{"country": "US", "state": "WA", "segment": "enterprise"}).UserID)
+	assert.Equal(t, fallback, choose(t, TerritoryStrategy{}, store, map[string]interface{}{"country": "US", "state": "NY"}).UserID)
+}
+
+func TestCustomStrategyScoresUsersWithLogic(t *testing.T) {
+	busy, away, free, idle := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{RuleType: types.AssignmentRuleTypeCustom, AssignmentConfig: types.AssignmentConfig{
+			Users:  []uuid.UUID{busy, away, free, idle},
+			Logic:  `user.is_available && user.active_assignments < params.capacity ? (lead.priority == "urgent" ? user.weight * 2 : user.weight) : false`,
+			Params: map[string]interface{}{"capacity": 5},
+		}},
+		loads: map[uuid.UUID]types.UserAssignmentLoad{
+			busy: {IsAvailable: true, ActiveAssignments: 5, Weight: 3},
+			away: {IsAvailable: false, Weight: 3},
+			free: {IsAvailable: true, ActiveAssignments: 2, Weight: 2},
+			idle: {IsAvailable: true, ActiveAssignments: 1, Weight: 2},
+		},
+	}
+
+	// free and idle tie on weight; idle has fewer active assignments
+	assert.Equal(t, idle, choose(t, CustomStrategy{}, store, map[string]interface{}{"priority": "urgent"}).UserID)
+
+	store.rule.AssignmentConfig.Logic = `lead.priority`
+	_, err := CustomStrategy{}.Choose(context.Background(), AssignmentRequest{Rule: store.rule, Attributes: map[string]interface{}{"priority": "low"}}, store)
+	assert.ErrorContains(t, err, "expected a boolean or a number")
+}
+
+func TestEngineRecordsAssignmentAndRetriesConflicts(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{ID: uuid.New(), Name: "Inbound", RuleType: types.AssignmentRuleTypeRoundRobin,
+			AssignmentConfig: types.AssignmentConfig{Users: []uuid.UUID{a, b, c}}},
+		conflicts: 1,
+	}
+	engine := NewAssignmentEngine(store)
+	lead := &types.Lead{ID: uuid.New(), OrganizationID: uuid.New(), AssignedTo: &c}
+
+	// Another assignment took a's turn, so the lead goes to b
+	result, err := engine.AssignLead(context.Background(), lead, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &types.AssignmentResult{LeadID: lead.ID, AssignedToID: b, AssignedToName: "Rep", Reason: "reassignment", Changed: true}, result)
+	require.Len(t, store.records, 1)
+	assert.Equal(t, types.AssignmentRecord{
+		OrganizationID: lead.OrganizationID, RuleID: store.rule.ID, RuleName: "Inbound", TargetModel: "leads", TargetID: lead.ID,
+		AssignedToID: b, PreviousAssignedToID: &c, Reason: "reassignment", Cursor: &types.AssignmentCursor{From: 1, To: 2},
+	}, store.records[0])
+
+	// The lead already with c is left as is
+	result, err = engine.AssignLead(context.Background(), lead, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "already_assigned", result.Reason)
+	assert.False(t, result.Changed)
+	assert.Len(t, store.records, 1)
+
+	store.conflicts = maxAssignmentAttempts
+	_, err = engine.AssignLead(context.Background(), &types.Lead{ID: uuid.New()}, nil)
+	assert.ErrorIs(t, err, types.ErrAssignmentConflict)
+
+	store.rule = nil
+	_, err = engine.AssignLead(context.Background(), lead, nil)
+	assert.ErrorIs(t, err, types.ErrNoMatchingAssignmentRule)
+}
+
+func TestEnginePoolRuleReturnsLeadToPool(t *testing.T) {
+	owner := uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{ID: uuid.New(), Name: "Rotting", RuleType: types.AssignmentRuleTypePool},
+	}
+	engine := NewAssignmentEngine(store)
+	lead := &types.Lead{ID: uuid.New(), OrganizationID: uuid.New(), AssignedTo: &owner}
+
+	result, err := engine.AssignLead(context.Background(), lead, map[string]interface{}{"rotting": true})
+	require.NoError(t, err)
+	assert.Equal(t, &types.AssignmentResult{LeadID: lead.ID, Reason: "returned_to_pool", Changed: true}, result)
+	require.Len(t, store.records, 1)
+	assert.Equal(t, types.AssignmentRecord{
+		OrganizationID: lead.OrganizationID, RuleID: store.rule.ID, RuleName: "Rotting", TargetModel: "leads", TargetID: lead.ID,
+		PreviousAssignedToID: &owner, Reason: "returned_to_pool",
+	}, store.records[0])
+
+	// A lead already in the pool stays there
+	result, err = engine.AssignLead(context.Background(), &types.Lead{ID: uuid.New()}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "already_in_pool", result.Reason)
+	assert.False(t, result.Changed)
+	assert.Len(t, store.records, 1)
+}
+
+// workingHours keeps users' working-hours calendars in memory
+type workingHours map[uuid.UUID]*calendar.Calendar
+
+func (w workingHours) ResolveUsers(_ context.Context, _ uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*calendar.Calendar, error) {
+	calendars := make(map[uuid.UUID]*calendar.Calendar)
+	for _, id := range userIDs {
+		if cal, ok := w[id]; ok {
+			calendars[id] = cal
+		}
+	}
+	return calendars, nil
+}
+
+func TestEngineSkipsUsersOffShiftAndSpillsOver(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	nine2five := []calendar.Interval{{Start: 9 * 60, End: 17 * 60}}
+	shift := func(loc *time.Location, holidays ...calendar.Holiday) *calendar.Calendar {
+		cal, err := calendar.New(loc, calendar.WeeklyHours{time.Monday: nine2five, time.Tuesday: nine2five}, holidays)
+		require.NoError(t, err)
+		return cal
+	}
+
+	london, osaka, night := uuid.New(), uuid.New(), uuid.New()
+	store := &assignmentStore{
+		rule: &types.AssignmentRule{ID: uuid.New(), Name: "Inbound", RuleType: types.AssignmentRuleTypeRoundRobin,
+			AssignmentConfig: types.AssignmentConfig{Users: []uuid.UUID{london, osaka}}},
+	}
+	engine := NewAssignmentEngine(store)
+	engine.SetWorkingHours(workingHours{
+		london: shift(time.UTC),
+		osaka:  shift(tokyo, calendar.Holiday{Date: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC), Name: "Closure"}),
+		night:  shift(tokyo, calendar.Holiday{Date: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC), Name: "Closure"}),
+	})
+
+	// Monday 10:00 in Tokyo is 01:00 in London
+	engine.now = func() time.Time { return time.Date(2025, 3, 3, 10, 0, 0, 0, tokyo) }
+	result, err := engine.AssignLead(context.Background(), &types.Lead{ID: uuid.New()}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, osaka, result.AssignedToID)
+	assert.Equal(t, "auto_assignment", result.Reason)
+
+	// Tuesday 01:00 in London is an Osaka closure day, so nobody is on shift
+	engine.now = func() time.Time { return time.Date(2025, 3, 4, 1, 0, 0, 0, time.UTC) }
+	lead := &types.Lead{ID: uuid.New(), OrganizationID: uuid.New()}
+	result, err = engine.AssignLead(context.Background(), lead, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &types.AssignmentResult{LeadID: lead.ID, Reason: "off_shift_pool"}, result)
+	assert.Len(t, store.records, 1)
+
+	// Fallback users take it whatever their own hours
+	store.rule.AssignmentConfig.FallbackUsers = []uuid.UUID{night}
+	result, err = engine.AssignLead(context.Background(), lead, nil)
+	require.NoError(t, err)
+	assert.Equal(t, night, result.AssignedToID)
+	assert.Equal(t, "off_shift_fallback", result.Reason)
+	require.Len(t, store.records, 2)
+	assert.Equal(t, "off_shift_fallback", store.records[1].Reason)
+}
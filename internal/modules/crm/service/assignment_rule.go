@@ -8,17 +8,28 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
 	"github.com/KevTiv/alieze-erp/pkg/events"
 
 	"github.com/google/uuid"
 )
 
+// BusinessCalendarResolver returns the business hours that apply to a team
+type BusinessCalendarResolver interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*calendar.Calendar, error)
+}
+
 // AssignmentRuleService handles business logic for assignment rules
 type AssignmentRuleService struct {
-	repo        types.AssignmentRuleRepository
-	authService auth.LegacyAuthService
-	eventBus    *events.Bus
-	logger      *log.Logger
+	repo         types.AssignmentRuleRepository
+	authService  auth.LegacyAuthService
+	eventBus     *events.Bus
+	calendars    BusinessCalendarResolver
+	entitlements entitlements.Checker
+	engine       *AssignmentEngine
+	logger       *log.Logger
+	now          func() time.Time
 }
 
 // NewAssignmentRuleService creates a new assignment rule service
@@ -27,8 +38,30 @@ func NewAssignmentRuleService(repo types.AssignmentRuleRepository, authService a
 		repo:        repo,
 		authService: authService,
 		eventBus:    eventBus,
+		engine:      NewAssignmentEngine(repo),
 		logger:      log.New(log.Writer(), "assignment-rule-service: ", log.LstdFlags),
+		now:         time.Now,
+	}
+}
+
+// SetEntitlements makes creating and activating rules check the
+// organization's plan, which caps its active rules as automations
+func (s *AssignmentRuleService) SetEntitlements(checker entitlements.Checker) {
+	s.entitlements = checker
+}
+
+// checkAutomationLimit checks the organization can have one more active rule
+func (s *AssignmentRuleService) checkAutomationLimit(ctx context.Context, orgID uuid.UUID) error {
+	if s.entitlements == nil {
+		return nil
 	}
+	return s.entitlements.CheckLimit(ctx, orgID, entitlements.LimitAutomations, 1)
+}
+
+// SetBusinessCalendars makes automatic assignment wait for the lead team's
+// business hours. Without calendars leads are assigned around the clock.
+func (s *AssignmentRuleService) SetBusinessCalendars(calendars BusinessCalendarResolver) {
+	s.calendars = calendars
 }
 
 // CreateAssignmentRule creates a new assignment rule
@@ -46,6 +79,10 @@ func (s *AssignmentRuleService) CreateAssignmentRule(ctx context.Context, req *t
 		return nil, fmt.Errorf("target model is required")
 	}
 
+	if err := validateAssignmentConfig(types.AssignmentRuleType(req.RuleType), req.AssignmentConfig); err != nil {
+		return nil, err
+	}
+
 	// Get organization ID and user ID from context
 	orgID, err := s.authService.GetOrganizationID(ctx)
 	if err != nil {
@@ -57,6 +94,12 @@ func (s *AssignmentRuleService) CreateAssignmentRule(ctx context.Context, req *t
 		return nil, fmt.Errorf("failed to get user ID: %w", err)
 	}
 
+	if req.IsActive {
+		if err := s.checkAutomationLimit(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create assignment rule
 	rule := &types.AssignmentRule{
 		ID:                    uuid.New(),
@@ -86,6 +129,17 @@ func (s *AssignmentRuleService) CreateAssignmentRule(ctx context.Context, req *t
 	return createdRule, nil
 }
 
+// validateAssignmentConfig checks the logic of custom rules, so that rules
+// fail when saved rather than when leads are assigned
+func validateAssignmentConfig(ruleType types.AssignmentRuleType, config types.AssignmentConfig) error {
+	if ruleType == types.AssignmentRuleTypeCustom {
+		if _, err := config.CompileCustomLogic(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetAssignmentRule retrieves an assignment rule by ID
 func (s *AssignmentRuleService) GetAssignmentRule(ctx context.Context, id uuid.UUID) (*types.AssignmentRule, error) {
 	return s.repo.FindByID(ctx, id)
@@ -122,6 +176,11 @@ func (s *AssignmentRuleService) UpdateAssignmentRule(ctx context.Context, id uui
 		existingRule.Priority = *req.Priority
 	}
 	if req.IsActive != nil {
+		if *req.IsActive && !existingRule.IsActive {
+			if err := s.checkAutomationLimit(ctx, existingRule.OrganizationID); err != nil {
+				return nil, err
+			}
+		}
 		existingRule.IsActive = *req.IsActive
 	}
 	if req.Conditions != nil {
@@ -145,6 +204,9 @@ func (s *AssignmentRuleService) UpdateAssignmentRule(ctx context.Context, id uui
 	if req.ActiveDays != nil {
 		existingRule.ActiveDays = *req.ActiveDays
 	}
+	if err := validateAssignmentConfig(existingRule.RuleType, existingRule.AssignmentConfig); err != nil {
+		return nil, err
+	}
 	existingRule.UpdatedBy = userID
 	existingRule.UpdatedAt = time.Now()
 
@@ -307,45 +369,45 @@ func (s *AssignmentRuleService) AssignLead(ctx context.Context, leadID uuid.UUID
 		return nil, fmt.Errorf("failed to get lead: %w", err)
 	}
 
-	// Determine next assignee
-	assigneeID, assigneeName, err := s.repo.GetNextAssignee(ctx, "leads", conditions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get next assignee: %w", err)
-	}
-
-	if assigneeID == uuid.Nil {
-		return nil, fmt.Errorf("no suitable assignee found")
+	// Leave the lead unassigned outside business hours
+	if s.calendars != nil {
+		cal, err := s.calendars.Resolve(ctx, lead.OrganizationID, lead.TeamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve business calendar: %w", err)
+		}
+		if !cal.IsOpen(s.now()) {
+			return &types.AssignmentResult{
+				LeadID:  leadID,
+				Reason:  "outside_business_hours",
+				Changed: false,
+			}, nil
+		}
 	}
 
-	// Check if assignment is needed
-	if lead.AssignedTo != nil && *lead.AssignedTo == assigneeID {
-		return &types.AssignmentResult{
-			LeadID:         leadID,
-			AssignedToID:   assigneeID,
-			AssignedToName: assigneeName,
-			Reason:         "already_assigned",
-			Changed:        false,
-		}, nil
+	result, err := s.engine.AssignLead(ctx, lead, conditions)
+	if err != nil || !result.Changed {
+		return result, err
 	}
 
-	// Determine assignment reason
-	reason := "auto_assignment"
-	if lead.AssignedTo != nil {
-		reason = "reassignment"
+	event := types.LeadAssignedEvent{
+		OrganizationID:     lead.OrganizationID,
+		LeadID:             leadID,
+		PreviousAssignedTo: lead.AssignedTo,
+		Reason:             result.Reason,
+		AssignedAt:         s.now(),
 	}
-
-	err = s.repo.AssignLead(ctx, leadID, assigneeID, reason)
-	if err != nil {
-		return nil, fmt.Errorf("failed to assign lead: %w", err)
+	if result.AssignedToID != uuid.Nil {
+		assignedTo := result.AssignedToID
+		event.AssignedTo = &assignedTo
 	}
+	s.publishEvent(ctx, "crm.lead.assigned", event)
+	return result, nil
+}
 
-	return &types.AssignmentResult{
-		LeadID:         leadID,
-		AssignedToID:   assigneeID,
-		AssignedToName: assigneeName,
-		Reason:         reason,
-		Changed:        true,
-	}, nil
+// Engine returns the engine choosing assignees, to register strategies of
+// other rule types with
+func (s *AssignmentRuleService) Engine() *AssignmentEngine {
+	return s.engine
 }
 
 // getLead is a helper function to get lead details
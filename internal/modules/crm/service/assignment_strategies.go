@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// canTakeAssignment reports whether a user can take another assignment of a
+// rule: they are available and below both their capacity and the rule's
+// maximum per user, where 0 is unlimited
+func canTakeAssignment(load types.UserAssignmentLoad, rule *types.AssignmentRule) bool {
+	if !load.IsAvailable {
+		return false
+	}
+	if load.MaxCapacity > 0 && load.ActiveAssignments >= load.MaxCapacity {
+		return false
+	}
+	return rule.MaxAssignmentsPerUser <= 0 || load.ActiveAssignments < rule.MaxAssignmentsPerUser
+}
+
+// candidateLoads lists the loads of users, checking there is one per user
+func candidateLoads(ctx context.Context, req AssignmentRequest, data AssignmentData, users []uuid.UUID) ([]types.UserAssignmentLoad, error) {
+	loads, err := data.ListCandidateLoads(ctx, req.Rule.OrganizationID, req.TargetModel, users)
+	if err != nil {
+		return nil, err
+	}
+	if len(loads) != len(users) {
+		return nil, fmt.Errorf("got %d user loads for %d users", len(loads), len(users))
+	}
+	return loads, nil
+}
+
+// leastLoaded returns the user who can take the assignment with the fewest
+// active assignments, the first listed on ties
+func leastLoaded(loads []types.UserAssignmentLoad, rule *types.AssignmentRule) uuid.UUID {
+	best := -1
+	for i, load := range loads {
+		if canTakeAssignment(load, rule) && (best < 0 || load.ActiveAssignments < loads[best].ActiveAssignments) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return uuid.Nil
+	}
+	return loads[best].UserID
+}
+
+// RoundRobinStrategy assigns the rule's users in turn, from its current
+// index, skipping users who cannot take the assignment
+type RoundRobinStrategy struct{}
+
+func (RoundRobinStrategy) Choose(ctx context.Context, req AssignmentRequest, data AssignmentData) (*AssignmentChoice, error) {
+	users := req.Rule.AssignmentConfig.Users
+	if len(users) == 0 {
+		return &AssignmentChoice{}, nil
+	}
+	loads, err := candidateLoads(ctx, req, data, users)
+	if err != nil {
+		return nil, err
+	}
+
+	current := req.Rule.AssignmentConfig.CurrentIndex
+	start := ((current % len(users)) + len(users)) % len(users)
+	for i := range users {
+		next := (start + i) % len(users)
+		if canTakeAssignment(loads[next], req.Rule) {
+			return &AssignmentChoice{
+				UserID: users[next],
+				Cursor: &types.AssignmentCursor{From: current, To: (next + 1) % len(users)},
+			}, nil
+		}
+	}
+	return &AssignmentChoice{}, nil
+}
+
+// WeightedStrategy assigns the user with the fewest active assignments for
+// their weight, the first listed on ties. Weights below 1 count as 1.
+type WeightedStrategy struct{}
+
+func (WeightedStrategy) Choose(ctx context.Context, req AssignmentRequest, data AssignmentData) (*AssignmentChoice, error) {
+	assignments := req.Rule.AssignmentConfig.Assignments
+	if len(assignments) == 0 {
+		return &AssignmentChoice{}, nil
+	}
+	users := make([]uuid.UUID, len(assignments))
+	for i, a := range assignments {
+		users[i] = a.UserID
+	}
+	loads, err := candidateLoads(ctx, req, data, users)
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &AssignmentChoice{}
+	var bestScore float64
+	for i, a := range assignments {
+		if !canTakeAssignment(loads[i], req.Rule) {
+			continue
+		}
+		weight := max(a.Weight, 1)
+		score := float64(loads[i].ActiveAssignments) / float64(weight)
+		if choice.UserID == uuid.Nil || score < bestScore {
+			choice.UserID, bestScore = a.UserID, score
+		}
+	}
+	return choice, nil
+}
+
+// TerritoryStrategy assigns the least loaded user of the highest priority
+// active territory whose conditions the attributes meet. Each condition
+// names an attribute and its value, or a list of values it may take.
+type TerritoryStrategy struct{}
+
+func (TerritoryStrategy) Choose(ctx context.Context, req AssignmentRequest, data AssignmentData) (*AssignmentChoice, error) {
+	territories, err := data.ListTerritories(ctx, req.Rule.OrganizationID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, territory := range territories {
+		if len(territory.AssignedUsers) == 0 || !territoryMatches(territory, req.Attributes) {
+			continue
+		}
+		loads, err := candidateLoads(ctx, req, data, territory.AssignedUsers)
+		if err != nil {
+			return nil, err
+		}
+		// A territory without a free user falls through to the next
+		if userID := leastLoaded(loads, req.Rule); userID != uuid.Nil {
+			return &AssignmentChoice{UserID: userID}, nil
+		}
+	}
+	return &AssignmentChoice{}, nil
+}
+
+func territoryMatches(territory *types.Territory, attributes map[string]interface{}) bool {
+	conditions, _ := territory.Conditions.(map[string]interface{})
+	for field, want := range conditions {
+		got, ok := attributes[field]
+		if !ok {
+			return false
+		}
+		values, isList := want.([]interface{})
+		if !isList {
+			values = []interface{}{want}
+		}
+		matched := false
+		for _, value := range values {
+			if fmt.Sprint(value) == fmt.Sprint(got) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// CustomStrategy scores the rule's users with its logic, see
+// types.AssignmentConfig.CompileCustomLogic. The eligible user with the
+// highest score is assigned, the one with fewer active assignments and then
+// the first listed on ties.
+type CustomStrategy struct{}
+
+func (CustomStrategy) Choose(ctx context.Context, req AssignmentRequest, data AssignmentData) (*AssignmentChoice, error) {
+	config := req.Rule.AssignmentConfig
+	logic, err := config.CompileCustomLogic()
+	if err != nil {
+		return nil, err
+	}
+	loads, err := candidateLoads(ctx, req, data, config.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	best := -1
+	var bestScore float64
+	for i, load := range loads {
+		user := map[string]interface{}{
+			"id":                     load.UserID.String(),
+			"active_assignments":     load.ActiveAssignments,
+			"total_assignments":      load.TotalAssignments,
+			"max_capacity":           load.MaxCapacity,
+			"weight":                 load.Weight,
+			"is_available":           load.IsAvailable,
+			"hours_since_assignment": nil,
+		}
+		if !load.LastAssignedAt.IsZero() {
+			user["hours_since_assignment"] = req.Now.Sub(load.LastAssignedAt).Hours()
+		}
+
+		result, err := logic.Evaluate(map[string]interface{}{
+			"lead":   req.Attributes,
+			"user":   user,
+			"params": config.Params,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var score float64
+		switch v := result.(type) {
+		case bool:
+			if !v {
+				continue
+			}
+			score = 1
+		case float64:
+			if v <= 0 {
+				continue
+			}
+			score = v
+		case nil:
+			continue
+		default:
+			return nil, fmt.Errorf("logic %q returned %v, expected a boolean or a number", logic, result)
+		}
+
+		if best < 0 || score > bestScore || (score == bestScore && load.ActiveAssignments < loads[best].ActiveAssignments) {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 {
+		return &AssignmentChoice{}, nil
+	}
+	return &AssignmentChoice{UserID: loads[best].UserID}, nil
+}
+
+// PoolStrategy returns records to the pool of unassigned records
+type PoolStrategy struct{}
+
+func (PoolStrategy) Choose(context.Context, AssignmentRequest, AssignmentData) (*AssignmentChoice, error) {
+	return &AssignmentChoice{Release: true}, nil
+}
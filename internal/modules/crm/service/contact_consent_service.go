@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// UnsubscribePath is the public unsubscribe link, followed by the
+	// contact's token
+	UnsubscribePath = "/public/v1/unsubscribe/"
+	// ConsentConfirmPath is the public double opt-in link, followed by the
+	// confirmation token
+	ConsentConfirmPath = "/public/v1/consent/confirm/"
+	// ConsentConfirmationLifetime is how long a double opt-in link works
+	ConsentConfirmationLifetime = 7 * 24 * time.Hour
+	// consentSendTimeout bounds emailing a confirmation link
+	consentSendTimeout = 30 * time.Second
+	// maxConsentNote bounds the note of a consent change
+	maxConsentNote = 1000
+)
+
+// ConsentMailer emails double opt-in confirmation links
+type ConsentMailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// ConsentLink is the client a public link was used from
+type ConsentLink struct {
+	IPAddress string
+	UserAgent string
+}
+
+// ContactConsentService records contacts' communication preferences per
+// channel, confirms email opt-ins from the contact and tells the services
+// that reach contacts whether they may
+type ContactConsentService struct {
+	repo        types.ContactConsentRepository
+	contactRepo types.ContactRepository
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	mailer      ConsentMailer
+	publicURL   string
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewContactConsentService(
+	repo types.ContactConsentRepository,
+	contactRepo types.ContactRepository,
+	authService auth.LegacyAuthService,
+	eventBus *events.Bus,
+) *ContactConsentService {
+	return &ContactConsentService{
+		repo:        repo,
+		contactRepo: contactRepo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "contact-consent"),
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails double opt-in confirmation links. Without a mailer and
+// a public URL, email opt-ins can only be recorded directly.
+func (s *ContactConsentService) SetMailer(mailer ConsentMailer) {
+	s.mailer = mailer
+}
+
+// SetPublicURL sets the API's public address confirmation links open
+func (s *ContactConsentService) SetPublicURL(url string) {
+	s.publicURL = strings.TrimRight(url, "/")
+}
+
+// Allows reports whether the contact may be reached on the channel, see
+// ContactConsent.Allows. Services sending to contacts call it before every
+// message.
+func (s *ContactConsentService) Allows(ctx context.Context, orgID, contactID uuid.UUID, channel string) (bool, error) {
+	consents, err := s.repo.ListConsents(ctx, orgID, contactID)
+	if err != nil {
+		return false, err
+	}
+	return channelConsent(orgID, contactID, consents, types.ContactConsentChannel(channel)).Allows(), nil
+}
+
+// GetPreferences returns the contact's preference for every channel and
+// its unsubscribe link
+func (s *ContactConsentService) GetPreferences(ctx context.Context, contactID uuid.UUID) (*types.ContactPreferences, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:contacts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.preferences(ctx, orgID, contactID)
+}
+
+func (s *ContactConsentService) preferences(ctx context.Context, orgID, contactID uuid.UUID) (*types.ContactPreferences, error) {
+	consents, err := s.repo.ListConsents(ctx, orgID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newConsentToken()
+	if err != nil {
+		return nil, err
+	}
+	if token, err = s.repo.UnsubscribeToken(ctx, orgID, contactID, token); err != nil {
+		return nil, err
+	}
+
+	return &types.ContactPreferences{
+		ContactID:       contactID,
+		Channels:        everyChannel(orgID, contactID, consents),
+		UnsubscribePath: UnsubscribePath + token,
+	}, nil
+}
+
+// everyChannel fills in the channels without a stated preference
+func everyChannel(orgID, contactID uuid.UUID, consents []types.ContactConsent) []types.ContactConsent {
+	channels := make([]types.ContactConsent, 0, len(types.ContactConsentChannels))
+	for _, channel := range types.ContactConsentChannels {
+		channels = append(channels, channelConsent(orgID, contactID, consents, channel))
+	}
+	return channels
+}
+
+func channelConsent(orgID, contactID uuid.UUID, consents []types.ContactConsent, channel types.ContactConsentChannel) types.ContactConsent {
+	for _, consent := range consents {
+		if consent.Channel == channel {
+			return consent
+		}
+	}
+	return types.ContactConsent{OrganizationID: orgID, ContactID: contactID, Channel: channel, Status: types.ContactConsentUnknown}
+}
+
+// SetConsent records the contact's preference for a channel. An email
+// opt-in with double opt-in stays pending, and the contact is not emailed,
+// until the contact confirms it from the link emailed to them.
+func (s *ContactConsentService) SetConsent(ctx context.Context, contactID uuid.UUID, req types.ContactConsentRequest) (*types.ContactConsent, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:contacts:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := s.authService.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	note := strings.TrimSpace(req.Note)
+	switch {
+	case !req.Channel.IsValid():
+		return nil, fmt.Errorf("%w: channel must be email, sms or phone", types.ErrInvalidConsent)
+	case req.Status != types.ContactConsentOptedIn && req.Status != types.ContactConsentOptedOut:
+		return nil, fmt.Errorf("%w: status must be opted_in or opted_out", types.ErrInvalidConsent)
+	case req.DoubleOptIn && (req.Channel != types.ContactConsentEmail || req.Status != types.ContactConsentOptedIn):
+		return nil, fmt.Errorf("%w: double opt-in only applies to email opt-ins", types.ErrInvalidConsent)
+	case utf8.RuneCountInString(note) > maxConsentNote:
+		return nil, fmt.Errorf("%w: note must be %d characters or less", types.ErrInvalidConsent, maxConsentNote)
+	}
+
+	consents, err := s.repo.ListConsents(ctx, orgID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	consent := channelConsent(orgID, contactID, consents, req.Channel)
+
+	now := s.now()
+	consent.Source, consent.UpdatedBy, consent.ConfirmationTokenHash = types.ContactConsentSourceUser, &userID, ""
+	var confirmation *types.Contact
+	var token string
+	switch {
+	case req.Status == types.ContactConsentOptedOut:
+		consent.Status, consent.OptedOutAt = types.ContactConsentOptedOut, &now
+	case req.DoubleOptIn:
+		if s.mailer == nil || s.publicURL == "" {
+			return nil, types.ErrConsentUnavailable
+		}
+		if confirmation, err = s.contactRepo.FindByID(ctx, contactID); err != nil {
+			return nil, err
+		}
+		if confirmation.OrganizationID != orgID || confirmation.Email == nil || strings.TrimSpace(*confirmation.Email) == "" {
+			return nil, fmt.Errorf("%w: the contact has no email address to confirm", types.ErrInvalidConsent)
+		}
+		if token, err = newConsentToken(); err != nil {
+			return nil, err
+		}
+		consent.Status, consent.ConfirmationSentAt, consent.ConfirmedAt = types.ContactConsentPending, &now, nil
+		consent.ConfirmationTokenHash = hashConsentToken(token)
+	default:
+		consent.Status, consent.OptedInAt, consent.ConfirmedAt = types.ContactConsentOptedIn, &now, nil
+	}
+
+	saved, err := s.repo.SaveConsent(ctx, consent, types.ContactConsentEvent{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ContactID:      contactID,
+		Channel:        consent.Channel,
+		Status:         consent.Status,
+		Source:         types.ContactConsentSourceUser,
+		ActorID:        &userID,
+		Note:           note,
+		CreatedAt:      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmation != nil {
+		if err := s.sendConfirmation(ctx, confirmation, token); err != nil {
+			return nil, err
+		}
+	}
+
+	s.publishEvent(ctx, "contact.consent_changed", saved)
+	return saved, nil
+}
+
+func (s *ContactConsentService) sendConfirmation(ctx context.Context, contact *types.Contact, token string) error {
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm that you want to receive emails from us by opening this link within %d days:\n\n%s%s%s\n\nIf you did not ask for this, you can ignore this email and you will not be subscribed.\n",
+		contact.Name, int(ConsentConfirmationLifetime.Hours()/24), s.publicURL, ConsentConfirmPath, token)
+
+	sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, contact.OrganizationID), consentSendTimeout)
+	defer cancel()
+	if err := s.mailer.Send(sendCtx, strings.TrimSpace(*contact.Email), "Please confirm your subscription", body); err != nil {
+		return fmt.Errorf("failed to email opt-in confirmation: %w", err)
+	}
+	return nil
+}
+
+// ListHistory returns every change of the contact's consents, newest first
+func (s *ContactConsentService) ListHistory(ctx context.Context, contactID uuid.UUID) ([]types.ContactConsentEvent, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:contacts:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.ListConsents(ctx, orgID, contactID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListConsentEvents(ctx, orgID, contactID)
+}
+
+// ConfirmOptIn opts the contact in from the double opt-in link they were
+// emailed. Links work once, for ConsentConfirmationLifetime.
+func (s *ContactConsentService) ConfirmOptIn(ctx context.Context, token string, link ConsentLink) (*types.PublicContactConsent, error) {
+	now := s.now()
+	consent, err := s.repo.ConfirmConsent(ctx, hashConsentToken(token), now.Add(-ConsentConfirmationLifetime), types.ContactConsentEvent{
+		ID:        uuid.New(),
+		Status:    types.ContactConsentOptedIn,
+		Source:    types.ContactConsentSourceDoubleOptIn,
+		IPAddress: link.IPAddress,
+		UserAgent: truncateRunes(link.UserAgent, 500),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Contact confirmed opt-in", "contact_id", consent.ContactID, "channel", consent.Channel)
+	s.publishEvent(ctx, "contact.consent_changed", consent)
+	return &types.PublicContactConsent{Channel: consent.Channel, Status: consent.Status}, nil
+}
+
+// GetPublicPreferences returns what the unsubscribe page of a token shows:
+// the status of each channel, without anything identifying the contact
+func (s *ContactConsentService) GetPublicPreferences(ctx context.Context, token string) (*types.PublicContactPreferences, error) {
+	orgID, contactID, err := s.repo.FindUnsubscribeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.repo.ListConsents(ctx, orgID, contactID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrConsentTokenNotFound
+		}
+		return nil, err
+	}
+
+	preferences := &types.PublicContactPreferences{}
+	for _, consent := range everyChannel(orgID, contactID, consents) {
+		preferences.Channels = append(preferences.Channels, types.PublicContactConsent{Channel: consent.Channel, Status: consent.Status})
+	}
+	return preferences, nil
+}
+
+// Unsubscribe opts the contact of an unsubscribe token out of the channel,
+// email when none is given. Unsubscribing again changes nothing.
+func (s *ContactConsentService) Unsubscribe(ctx context.Context, token string, channel types.ContactConsentChannel, link ConsentLink) (*types.PublicContactConsent, error) {
+	if channel == "" {
+		channel = types.ContactConsentEmail
+	}
+	if !channel.IsValid() {
+		return nil, fmt.Errorf("%w: channel must be email, sms or phone", types.ErrInvalidConsent)
+	}
+
+	orgID, contactID, err := s.repo.FindUnsubscribeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.repo.ListConsents(ctx, orgID, contactID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, types.ErrConsentTokenNotFound
+		}
+		return nil, err
+	}
+	consent := channelConsent(orgID, contactID, consents, channel)
+	if consent.Status == types.ContactConsentOptedOut {
+		return &types.PublicContactConsent{Channel: channel, Status: consent.Status}, nil
+	}
+
+	now := s.now()
+	consent.Status, consent.Source, consent.OptedOutAt = types.ContactConsentOptedOut, types.ContactConsentSourceUnsubscribe, &now
+	consent.UpdatedBy, consent.ConfirmationTokenHash = nil, ""
+	saved, err := s.repo.SaveConsent(ctx, consent, types.ContactConsentEvent{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ContactID:      contactID,
+		Channel:        channel,
+		Status:         types.ContactConsentOptedOut,
+		Source:         types.ContactConsentSourceUnsubscribe,
+		IPAddress:      link.IPAddress,
+		UserAgent:      truncateRunes(link.UserAgent, 500),
+		CreatedAt:      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Contact unsubscribed", "contact_id", contactID, "channel", channel)
+	s.publishEvent(ctx, "contact.consent_changed", saved)
+	return &types.PublicContactConsent{Channel: saved.Channel, Status: saved.Status}, nil
+}
+
+func (s *ContactConsentService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish contact consent event", "event", eventType, "error", err)
+		}
+	}
+}
+
+func newConsentToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate consent token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashConsentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
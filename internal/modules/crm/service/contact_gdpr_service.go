@@ -0,0 +1,321 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/crm/errors"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+)
+
+// gdprPermission is needed to export a contact's data and to request,
+// approve or reject its anonymization
+const gdprPermission = "crm:contacts:gdpr"
+
+// ContactGDPRService exports the data held about contacts and anonymizes
+// them once a second user approves
+type ContactGDPRService struct {
+	repo        types.ContactGDPRRepository
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewContactGDPRService(
+	repo types.ContactGDPRRepository,
+	authService auth.LegacyAuthService,
+	eventBus *events.Bus,
+) *ContactGDPRService {
+	return &ContactGDPRService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "contact-gdpr"),
+		now:         time.Now,
+	}
+}
+
+// Export builds a zip archive of every section of the data held about the
+// contact, with a manifest, and records the export with the archive's
+// SHA-256
+func (s *ContactGDPRService) Export(ctx context.Context, contactID uuid.UUID) (*types.ContactGDPRArchive, error) {
+	orgID, userID, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := s.repo.ExportSections(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "EXPORT_FAILED", "failed to export contact data")
+	}
+
+	exportedAt := s.now().UTC()
+	req := &types.ContactGDPRRequest{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ContactID:      contactID,
+		Kind:           types.ContactGDPRKindExport,
+		Status:         types.ContactGDPRStatusCompleted,
+		RequestedBy:    userID,
+		CompletedAt:    &exportedAt,
+		Summary:        make(map[string]int64, len(sections)),
+	}
+	manifest := &types.ContactGDPRManifest{
+		ContactID:      contactID,
+		OrganizationID: orgID,
+		RequestID:      req.ID,
+		ExportedAt:     exportedAt,
+		ExportedBy:     userID,
+	}
+	for _, section := range sections {
+		req.Summary[section.Name] = section.Rows
+	}
+
+	data, err := writeGDPRArchive(manifest, sections)
+	if err != nil {
+		return nil, errors.Wrap(err, "EXPORT_FAILED", "failed to write contact data archive")
+	}
+	sum := sha256.Sum256(data)
+	req.SHA256 = hex.EncodeToString(sum[:])
+
+	if err := s.repo.CreateRequest(ctx, req); err != nil {
+		return nil, errors.Wrap(err, "EXPORT_FAILED", "failed to record contact data export")
+	}
+
+	s.publish(ctx, "contact.gdpr_exported", map[string]interface{}{
+		"organization_id": orgID.String(),
+		"contact_id":      contactID.String(),
+		"request_id":      req.ID.String(),
+		"exported_by":     userID.String(),
+	})
+
+	return &types.ContactGDPRArchive{
+		Request:  req,
+		Filename: "contact-" + contactID.String() + ".zip",
+		Data:     data,
+	}, nil
+}
+
+// RequestAnonymization records a pending anonymization of the contact,
+// which another user has to approve
+func (s *ContactGDPRService) RequestAnonymization(ctx context.Context, contactID uuid.UUID, body types.ContactAnonymizeRequest) (*types.ContactGDPRRequest, error) {
+	orgID, userID, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" {
+		return nil, errors.New("INVALID_INPUT", "reason is required")
+	}
+
+	anonymizedAt, err := s.repo.AnonymizedAt(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "GET_FAILED", "failed to get contact")
+	}
+	if anonymizedAt != nil {
+		return nil, errors.Wrap(types.ErrContactAnonymized, "CONFLICT", types.ErrContactAnonymized.Error())
+	}
+
+	req := &types.ContactGDPRRequest{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ContactID:      contactID,
+		Kind:           types.ContactGDPRKindAnonymize,
+		Status:         types.ContactGDPRStatusPending,
+		Reason:         reason,
+		RequestedBy:    userID,
+	}
+	if err := s.repo.CreateRequest(ctx, req); err != nil {
+		if stderrors.Is(err, types.ErrContactGDPRRequestPending) {
+			return nil, errors.Wrap(err, "CONFLICT", err.Error())
+		}
+		return nil, errors.Wrap(err, "CREATE_FAILED", "failed to request anonymization")
+	}
+
+	s.publish(ctx, "contact.anonymization_requested", map[string]interface{}{
+		"organization_id": orgID.String(),
+		"contact_id":      contactID.String(),
+		"request_id":      req.ID.String(),
+		"requested_by":    userID.String(),
+	})
+
+	return req, nil
+}
+
+// ApproveAnonymization irreversibly pseudonymizes the contact of the
+// pending request. The requester cannot approve their own request.
+func (s *ContactGDPRService) ApproveAnonymization(ctx context.Context, contactID uuid.UUID, review types.ContactGDPRReview) (*types.ContactGDPRRequest, error) {
+	orgID, userID, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.pendingAnonymization(ctx, orgID, contactID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.repo.Anonymize(ctx, orgID, pending.ID, userID, strings.TrimSpace(review.Note))
+	if err != nil {
+		if stderrors.Is(err, types.ErrContactAnonymized) {
+			return nil, errors.Wrap(err, "CONFLICT", err.Error())
+		}
+		return nil, errors.Wrap(err, "ANONYMIZE_FAILED", "failed to anonymize contact")
+	}
+
+	s.publish(ctx, "contact.anonymized", map[string]interface{}{
+		"organization_id": orgID.String(),
+		"contact_id":      contactID.String(),
+		"request_id":      req.ID.String(),
+		"requested_by":    req.RequestedBy.String(),
+		"approved_by":     userID.String(),
+		"anonymized":      req.Summary,
+	})
+
+	s.logger.Info("Anonymized contact",
+		"contact_id", contactID,
+		"request_id", req.ID,
+		"anonymized", req.Summary)
+
+	return req, nil
+}
+
+// RejectAnonymization closes the pending request without changing the
+// contact. The note explaining the rejection is required.
+func (s *ContactGDPRService) RejectAnonymization(ctx context.Context, contactID uuid.UUID, review types.ContactGDPRReview) (*types.ContactGDPRRequest, error) {
+	orgID, userID, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	note := strings.TrimSpace(review.Note)
+	if note == "" {
+		return nil, errors.New("INVALID_INPUT", "a note is required to reject an anonymization")
+	}
+
+	pending, err := s.pendingAnonymization(ctx, orgID, contactID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.repo.Reject(ctx, orgID, pending.ID, userID, note)
+	if err != nil {
+		return nil, errors.Wrap(err, "REJECT_FAILED", "failed to reject anonymization")
+	}
+
+	s.publish(ctx, "contact.anonymization_rejected", map[string]interface{}{
+		"organization_id": orgID.String(),
+		"contact_id":      contactID.String(),
+		"request_id":      req.ID.String(),
+		"rejected_by":     userID.String(),
+	})
+
+	return req, nil
+}
+
+// ListRequests returns the exports and anonymization requests of the
+// contact, newest first
+func (s *ContactGDPRService) ListRequests(ctx context.Context, contactID uuid.UUID) ([]types.ContactGDPRRequest, error) {
+	orgID, _, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.ListRequests(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "LIST_FAILED", "failed to list gdpr requests")
+	}
+	return requests, nil
+}
+
+// caller checks the GDPR permission and returns the caller's organization
+// and user. Every request is attributed, so the user is required.
+func (s *ContactGDPRService) caller(ctx context.Context) (uuid.UUID, uuid.UUID, error) {
+	if err := s.authService.CheckPermission(ctx, gdprPermission); err != nil {
+		return uuid.Nil, uuid.Nil, errors.Wrap(err, "PERMISSION_DENIED", "permission denied")
+	}
+
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.Wrap(err, "UNAUTHORIZED", "failed to get organization")
+	}
+	userID, err := s.authService.GetUserID(ctx)
+	if err != nil || userID == uuid.Nil {
+		return uuid.Nil, uuid.Nil, errors.Wrap(fmt.Errorf("no user in context: %v", err), "UNAUTHORIZED", "failed to get user")
+	}
+	return orgID, userID, nil
+}
+
+func (s *ContactGDPRService) pendingAnonymization(ctx context.Context, orgID, contactID, userID uuid.UUID) (*types.ContactGDPRRequest, error) {
+	pending, err := s.repo.PendingAnonymization(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "GET_FAILED", "no pending anonymization for contact")
+	}
+	if pending.RequestedBy == userID {
+		return nil, errors.New("PERMISSION_DENIED", "anonymizations must be reviewed by another user")
+	}
+	return pending, nil
+}
+
+func (s *ContactGDPRService) publish(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, eventType, payload)
+	}
+}
+
+// writeGDPRArchive writes one JSON file per section and the manifest
+// listing them into a zip archive
+func writeGDPRArchive(manifest *types.ContactGDPRManifest, sections []types.ContactGDPRSection) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, section := range sections {
+		path := section.Name + ".json"
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Deflate, Modified: manifest.ExportedAt})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", path, err)
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, section.Data, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", section.Name, err)
+		}
+		if _, err := indented.WriteTo(w); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, types.ContactGDPRManifestFile{
+			Path:    path,
+			Section: section.Name,
+			Rows:    section.Rows,
+		})
+	}
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "manifest.json", Method: zip.Deflate, Modified: manifest.ExportedAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add manifest to archive: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -3,41 +3,57 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"slices"
 
 	"github.com/google/uuid"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
 	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/crm/errors"
 	"github.com/KevTiv/alieze-erp/pkg/events"
 )
 
+const (
+	// DefaultDuplicateThreshold is the score from which detected pairs are stored
+	DefaultDuplicateThreshold = 80
+	// DefaultDuplicateCandidateScore is the score from which a contact's
+	// possible duplicates are listed: a shared email or phone number, or a
+	// closely matching name
+	DefaultDuplicateCandidateScore = 25
+	// maxDuplicateCandidates bounds the possible duplicates listed for a contact
+	maxDuplicateCandidates = 50
+)
+
 // ContactMergeService handles duplicate detection and contact merging
 type ContactMergeService struct {
-	repo           repository.ContactMergeRepository
-	contactRepo    types.ContactRepository
-	authService    auth.AuthorizationService
-	eventPublisher events.EventPublisher
+	repo        repository.ContactMergeRepository
+	contactRepo types.ContactRepository
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
 }
 
 func NewContactMergeService(
 	repo repository.ContactMergeRepository,
 	contactRepo types.ContactRepository,
-	authService auth.AuthorizationService,
-	eventPublisher events.EventPublisher,
+	authService auth.LegacyAuthService,
+	eventBus *events.Bus,
 ) *ContactMergeService {
 	return &ContactMergeService{
-		repo:           repo,
-		contactRepo:    contactRepo,
-		authService:    authService,
-		eventPublisher: eventPublisher,
+		repo:        repo,
+		contactRepo: contactRepo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "contact-merge"),
 	}
 }
 
 // DetectDuplicates initiates duplicate detection for an organization
 func (s *ContactMergeService) DetectDuplicates(ctx context.Context, orgID uuid.UUID, threshold *int, limit *int) (*types.DetectDuplicatesResponse, error) {
 	// Set default threshold if not provided
-	actualThreshold := 80
+	actualThreshold := DefaultDuplicateThreshold
 	if threshold != nil {
 		actualThreshold = *threshold
 	}
@@ -57,16 +73,14 @@ func (s *ContactMergeService) DetectDuplicates(ctx context.Context, orgID uuid.U
 	// Store detected duplicates in database
 	created := 0
 	for _, dup := range duplicates {
-		// Check if this duplicate pair already exists
-		err := s.repo.CreateDuplicate(ctx, dup)
-		if err != nil {
-			// Skip if already exists, otherwise return error
+		// Pairs that are already stored are skipped
+		if err := s.repo.CreateDuplicate(ctx, dup); err != nil {
 			continue
 		}
 		created++
 
 		// Publish event
-		s.eventPublisher.Publish(ctx, "contact.duplicate.detected", map[string]interface{}{
+		s.publish(ctx, "contact.duplicate.detected", map[string]interface{}{
 			"organization_id":  orgID.String(),
 			"duplicate_id":     dup.ID.String(),
 			"contact1_id":      dup.ContactID1.String(),
@@ -84,6 +98,34 @@ func (s *ContactMergeService) DetectDuplicates(ctx context.Context, orgID uuid.U
 	}, nil
 }
 
+// FindDuplicates lists the contacts of the caller's organization that may
+// duplicate contactID: those sharing its email address or normalized phone
+// number, or with a trigram-similar name, scoring at least minScore
+func (s *ContactMergeService) FindDuplicates(ctx context.Context, contactID uuid.UUID, minScore int, limit int) ([]*types.ContactDuplicateMatch, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:contacts:read"); err != nil {
+		return nil, errors.Wrap(err, "PERMISSION_DENIED", "permission denied")
+	}
+
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "UNAUTHORIZED", "failed to get organization")
+	}
+
+	if minScore < 0 || minScore > 100 {
+		return nil, errors.Wrap(fmt.Errorf("min_score %d is out of range", minScore), "INVALID_INPUT", "min_score must be between 0 and 100")
+	}
+	if limit <= 0 || limit > maxDuplicateCandidates {
+		limit = maxDuplicateCandidates
+	}
+
+	matches, err := s.repo.FindDuplicatesOf(ctx, orgID, contactID, minScore, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "FIND_DUPLICATES_FAILED", "failed to find duplicate contacts")
+	}
+
+	return matches, nil
+}
+
 // CalculateSimilarity calculates similarity score between two contacts
 func (s *ContactMergeService) CalculateSimilarity(ctx context.Context, orgID uuid.UUID, contact1ID uuid.UUID, contact2ID uuid.UUID) (*types.CalculateSimilarityResponse, error) {
 	// Get both contacts
@@ -109,52 +151,82 @@ func (s *ContactMergeService) CalculateSimilarity(ctx context.Context, orgID uui
 		Contact1ID:      contact1ID,
 		Contact2ID:      contact2ID,
 		SimilarityScore: float64(score),
-		IsDuplicate:     score >= 80,
+		IsDuplicate:     score >= DefaultDuplicateThreshold,
 	}, nil
 }
 
-// MergeContacts merges two contacts
-func (s *ContactMergeService) MergeContacts(ctx context.Context, orgID uuid.UUID, masterContactID uuid.UUID, mergeContactID uuid.UUID, strategy string, fieldSelections map[string]string) error {
-	// Validate that master and duplicate are different contacts
-	if masterContactID == mergeContactID {
-		return fmt.Errorf("master and merge contact IDs must be different")
+// MergeContacts merges req.DuplicateContactID into the master contact. The
+// duplicate's leads, invoices, activities and relationships move to the
+// master and the duplicate is deleted, all in one transaction. With
+// req.DryRun nothing is saved and the result previews the merge.
+func (s *ContactMergeService) MergeContacts(ctx context.Context, masterContactID uuid.UUID, req types.MergeContactRequest) (*types.ContactMergeResult, error) {
+	// A preview only reads; a merge changes the master and deletes the duplicate
+	permissions := []string{"crm:contacts:update", "crm:contacts:delete"}
+	if req.DryRun {
+		permissions = []string{"crm:contacts:read"}
+	}
+	for _, permission := range permissions {
+		if err := s.authService.CheckPermission(ctx, permission); err != nil {
+			return nil, errors.Wrap(err, "PERMISSION_DENIED", "permission denied")
+		}
+	}
+
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "UNAUTHORIZED", "failed to get organization")
+	}
+
+	if err := validateMergeRequest(masterContactID, req); err != nil {
+		return nil, errors.Wrap(err, "INVALID_INPUT", err.Error())
 	}
 
 	// Get both contacts to verify they exist and belong to the organization
 	masterContact, err := s.contactRepo.FindByID(ctx, masterContactID)
 	if err != nil {
-		return fmt.Errorf("failed to get master contact: %w", err)
+		return nil, errors.Wrap(err, "GET_FAILED", "failed to get master contact")
 	}
 
-	mergeContact, err := s.contactRepo.FindByID(ctx, mergeContactID)
+	mergeContact, err := s.contactRepo.FindByID(ctx, req.DuplicateContactID)
 	if err != nil {
-		return fmt.Errorf("failed to get merge contact: %w", err)
+		return nil, errors.Wrap(err, "GET_FAILED", "failed to get duplicate contact")
 	}
 
-	// Verify both contacts belong to the organization
 	if masterContact.OrganizationID != orgID || mergeContact.OrganizationID != orgID {
-		return fmt.Errorf("contacts do not belong to the specified organization")
+		return nil, errors.ErrOrganizationAccess
+	}
+
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = "keep_master"
 	}
+	fieldSelections := s.buildFieldSelections(strategy, req.FieldSelections, masterContact, mergeContact)
 
-	// Build field selections based on strategy
-	actualFieldSelections := s.buildFieldSelections(strategy, fieldSelections)
+	// The merge is attributed to the caller when known
+	userID, _ := s.authService.GetUserID(ctx)
 
-	// Perform merge
-	userID := uuid.Nil // In production, this would come from context
-	err = s.repo.MergeContacts(ctx, masterContactID, mergeContactID, actualFieldSelections, userID, orgID)
+	result, err := s.repo.MergeContacts(ctx, orgID, masterContactID, req.DuplicateContactID, strategy, fieldSelections, userID, req.DryRun)
 	if err != nil {
-		return fmt.Errorf("failed to merge contacts: %w", err)
+		return nil, errors.Wrap(err, "MERGE_FAILED", "failed to merge contacts")
 	}
 
-	// Publish event
-	s.eventPublisher.Publish(ctx, "contact.merged", map[string]interface{}{
+	if req.DryRun {
+		return result, nil
+	}
+
+	s.publish(ctx, "contact.merged", map[string]interface{}{
 		"organization_id":   orgID.String(),
 		"master_contact_id": masterContactID.String(),
-		"merge_contact_id":  mergeContactID.String(),
+		"merge_contact_id":  req.DuplicateContactID.String(),
 		"merge_strategy":    strategy,
+		"reassigned":        result.Reassigned,
 	})
 
-	return nil
+	s.logger.Info("Merged contacts",
+		"master_contact_id", masterContactID,
+		"merged_contact_id", req.DuplicateContactID,
+		"reassigned", result.Reassigned)
+
+	return result, nil
 }
 
 // ResolveDuplicate marks a duplicate as resolved without merging
@@ -169,25 +241,26 @@ func (s *ContactMergeService) ResolveDuplicate(ctx context.Context, orgID uuid.U
 		return fmt.Errorf("duplicate does not belong to the specified organization")
 	}
 
-	// Validate resolution type
-	validTypes := map[string]bool{
-		"false_positive": true,
-		"ignore":         true,
-		"merged":         true,
+	// Map the resolution to the stored status
+	statuses := map[string]string{
+		"false_positive": "false_positive",
+		"ignore":         "ignored",
+		"merged":         "merged",
 	}
-	if !validTypes[resolutionType] {
+	status, ok := statuses[resolutionType]
+	if !ok {
 		return fmt.Errorf("invalid resolution type: %s", resolutionType)
 	}
 
-	// Update status
-	userID := uuid.Nil // In production, this would come from context
-	err = s.repo.UpdateDuplicateStatus(ctx, duplicateID, "resolved", userID)
+	// The resolution is attributed to the caller when known
+	userID, _ := s.authService.GetUserID(ctx)
+	err = s.repo.UpdateDuplicateStatus(ctx, duplicateID, status, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update duplicate status: %w", err)
 	}
 
 	// Publish event
-	s.eventPublisher.Publish(ctx, "contact.duplicate.resolved", map[string]interface{}{
+	s.publish(ctx, "contact.duplicate.resolved", map[string]interface{}{
 		"organization_id": orgID.String(),
 		"duplicate_id":    duplicateID.String(),
 		"resolution_type": resolutionType,
@@ -203,6 +276,12 @@ func (s *ContactMergeService) ListDuplicates(ctx context.Context, orgID uuid.UUI
 		OrganizationID: orgID,
 		Status:         status,
 	}
+	if limit != nil {
+		filter.Limit = *limit
+	}
+	if offset != nil {
+		filter.Offset = *offset
+	}
 
 	// Get duplicates
 	duplicates, err := s.repo.ListDuplicates(ctx, filter)
@@ -245,26 +324,66 @@ func (s *ContactMergeService) GetMergeHistory(ctx context.Context, orgID uuid.UU
 	return history, nil
 }
 
+// validateMergeRequest checks the contacts, strategy and field selections
+// of a merge
+func validateMergeRequest(masterContactID uuid.UUID, req types.MergeContactRequest) error {
+	if req.DuplicateContactID == uuid.Nil {
+		return fmt.Errorf("duplicate_contact_id is required")
+	}
+	if masterContactID == req.DuplicateContactID {
+		return fmt.Errorf("a contact cannot be merged into itself")
+	}
+
+	switch req.Strategy {
+	case "", "keep_master", "keep_latest":
+		if len(req.FieldSelections) > 0 {
+			return fmt.Errorf("field_selections require the custom strategy")
+		}
+	case "custom":
+		for field, source := range req.FieldSelections {
+			if !slices.Contains(types.ContactMergeFields, field) {
+				return fmt.Errorf("field %q cannot be merged", field)
+			}
+			if source != "master" && source != "duplicate" {
+				return fmt.Errorf("field %q must be taken from master or duplicate", field)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid merge strategy: %s", req.Strategy)
+	}
+
+	return nil
+}
+
 // buildFieldSelections creates field selection map based on merge strategy
-func (s *ContactMergeService) buildFieldSelections(strategy string, customSelections map[string]string) map[string]string {
+func (s *ContactMergeService) buildFieldSelections(strategy string, customSelections map[string]string, master, duplicate *types.Contact) map[string]string {
 	selections := make(map[string]string)
 
 	switch strategy {
-	case "keep_master":
-		// All fields from master (default behavior)
-		return selections
-
 	case "keep_latest":
-		// This would require comparing updated_at timestamps
-		// For now, default to master
+		// Every field comes from the most recently updated contact
+		if duplicate.UpdatedAt.After(master.UpdatedAt) {
+			for _, field := range types.ContactMergeFields {
+				selections[field] = "duplicate"
+			}
+		}
 		return selections
 
 	case "custom":
 		// Use provided custom selections
-		return customSelections
+		for field, source := range customSelections {
+			selections[field] = source
+		}
+		return selections
 
 	default:
-		// Default to keep_master
+		// keep_master: all fields from master, filling the ones it lacks
 		return selections
 	}
 }
+
+func (s *ContactMergeService) publish(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, eventType, payload)
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/crm/errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ContactScoreInterval is how often the scores of all contacts are
+	// recalculated and recorded
+	ContactScoreInterval = 24 * time.Hour
+	// ContactScoreWindow is how far back activities count towards a score
+	ContactScoreWindow = 90 * 24 * time.Hour
+	// contactScoreBatchSize bounds the contacts scored per query
+	contactScoreBatchSize = 500
+	// defaultScoreHistoryDays and maxScoreHistoryDays bound the days of
+	// score history returned at once
+	defaultScoreHistoryDays = 90
+	maxScoreHistoryDays     = 366
+)
+
+// errScoringUnavailable is returned when the service has no score repository
+var errScoringUnavailable = errors.NewWithStatus("SERVICE_UNAVAILABLE", "contact scoring is not available", http.StatusServiceUnavailable)
+
+// SetScoring enables contact scores calculated from activity data
+func (s *ContactServiceV2) SetScoring(scores types.ContactScoreRepository) {
+	s.scores = scores
+}
+
+// CalculateContactScore calculates engagement and lead scores for a contact
+// from its activity, email and deal history, with the organization's weights
+func (s *ContactServiceV2) CalculateContactScore(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID) (*types.ContactScore, error) {
+	// Validate organization access
+	if err := s.GetAuthService().CheckOrganizationAccess(ctx, orgID); err != nil {
+		return nil, errors.ErrOrganizationAccess
+	}
+	if s.scores == nil {
+		return nil, errScoringUnavailable
+	}
+
+	// Check if contact exists
+	exists, err := s.GetRepository().(interface {
+		ContactExists(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	}).ContactExists(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "VALIDATION_FAILED", "failed to check contact existence")
+	}
+	if !exists {
+		return nil, errors.New("contact_not_found", "contact does not exist")
+	}
+
+	weights, err := s.scores.Weights(ctx, orgID)
+	if err != nil {
+		return nil, errors.Wrap(err, "QUERY_FAILED", "failed to get score weights")
+	}
+	signals, err := s.scores.Signals(ctx, orgID, contactID, time.Now().UTC(), ContactScoreWindow)
+	if err != nil {
+		return nil, errors.Wrap(err, "QUERY_FAILED", "failed to get contact engagement")
+	}
+
+	snapshot := signals.Score(*weights, ContactScoreWindow)
+	return &types.ContactScore{
+		EngagementScore: snapshot.EngagementScore,
+		LeadScore:       snapshot.LeadScore,
+		EngagementFactors: map[string]interface{}{
+			"window_days": int(ContactScoreWindow / (24 * time.Hour)),
+			"recency": map[string]interface{}{
+				"score":            snapshot.RecencyScore,
+				"weight":           weights.Recency,
+				"last_activity_at": signals.LastActivityAt,
+			},
+			"frequency": map[string]interface{}{
+				"score":      snapshot.FrequencyScore,
+				"weight":     weights.Frequency,
+				"activities": signals.Activities,
+			},
+			"response_rate": map[string]interface{}{
+				"score":           snapshot.ResponseScore,
+				"weight":          weights.ResponseRate,
+				"emails_sent":     signals.EmailsSent,
+				"emails_received": signals.EmailsReceived,
+			},
+			"won_deals": map[string]interface{}{
+				"score":  snapshot.WonDealsScore,
+				"weight": weights.WonDeals,
+				"count":  signals.WonDeals,
+			},
+		},
+		LeadFactors: map[string]interface{}{
+			"open_lead_probability": signals.OpenLeadProbability,
+		},
+		LastUpdated: snapshot.CalculatedAt,
+	}, nil
+}
+
+// GetScoreWeights returns the organization's score weights
+func (s *ContactServiceV2) GetScoreWeights(ctx context.Context, orgID uuid.UUID) (*types.ContactScoreWeights, error) {
+	if err := s.GetAuthService().CheckOrganizationAccess(ctx, orgID); err != nil {
+		return nil, errors.ErrOrganizationAccess
+	}
+	if s.scores == nil {
+		return nil, errScoringUnavailable
+	}
+
+	weights, err := s.scores.Weights(ctx, orgID)
+	if err != nil {
+		return nil, errors.Wrap(err, "QUERY_FAILED", "failed to get score weights")
+	}
+	return weights, nil
+}
+
+// UpdateScoreWeights changes the organization's score weights. Scores use
+// them from their next calculation; recorded history is kept as it was.
+func (s *ContactServiceV2) UpdateScoreWeights(ctx context.Context, orgID uuid.UUID, req types.ContactScoreWeightsRequest) (*types.ContactScoreWeights, error) {
+	user, err := s.GetAuthService().GetCurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "UNAUTHORIZED", "failed to get current user")
+	}
+	if err := s.GetAuthService().CheckUserPermission(ctx, user.ID, orgID, "crm:contact_score_weights:update"); err != nil {
+		return nil, errors.Wrap(err, "PERMISSION_DENIED", "permission denied")
+	}
+	if s.scores == nil {
+		return nil, errScoringUnavailable
+	}
+
+	now := time.Now()
+	weights := types.ContactScoreWeights{
+		OrganizationID: orgID,
+		Recency:        req.Recency,
+		Frequency:      req.Frequency,
+		ResponseRate:   req.ResponseRate,
+		WonDeals:       req.WonDeals,
+		UpdatedAt:      &now,
+		UpdatedBy:      &user.ID,
+	}
+	if err := weights.Validate(); err != nil {
+		return nil, errors.Wrap(err, "VALIDATION_ERROR", err.Error())
+	}
+
+	saved, err := s.scores.SaveWeights(ctx, weights)
+	if err != nil {
+		return nil, errors.Wrap(err, "UPDATE_FAILED", "failed to save score weights")
+	}
+
+	// Log operation
+	s.LogOperation(ctx, "update_contact_score_weights", orgID, map[string]interface{}{
+		"recency":       saved.Recency,
+		"frequency":     saved.Frequency,
+		"response_rate": saved.ResponseRate,
+		"won_deals":     saved.WonDeals,
+	})
+
+	// Publish event
+	s.PublishEvent(ctx, "contact.score_weights.updated", saved)
+
+	return saved, nil
+}
+
+// ContactScoreHistory returns the contact's daily scores between from and
+// to, by default over the last defaultScoreHistoryDays days
+func (s *ContactServiceV2) ContactScoreHistory(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID, from, to *time.Time) ([]types.ContactScoreSnapshot, error) {
+	if err := s.GetAuthService().CheckOrganizationAccess(ctx, orgID); err != nil {
+		return nil, errors.ErrOrganizationAccess
+	}
+	if s.scores == nil {
+		return nil, errScoringUnavailable
+	}
+
+	filter := types.ContactScoreHistoryFilter{OrganizationID: orgID, ContactID: contactID, To: time.Now().UTC()}
+	if to != nil {
+		filter.To = *to
+	}
+	filter.From = filter.To.AddDate(0, 0, -defaultScoreHistoryDays)
+	if from != nil {
+		filter.From = *from
+	}
+	if filter.From.After(filter.To) {
+		return nil, errors.New("INVALID_TIME_RANGE", "from must not be after to")
+	}
+	if filter.To.Sub(filter.From) > maxScoreHistoryDays*24*time.Hour {
+		return nil, errors.New("INVALID_TIME_RANGE", "score history spans at most 366 days")
+	}
+
+	// Check if contact exists
+	exists, err := s.GetRepository().(interface {
+		ContactExists(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	}).ContactExists(ctx, orgID, contactID)
+	if err != nil {
+		return nil, errors.Wrap(err, "VALIDATION_FAILED", "failed to check contact existence")
+	}
+	if !exists {
+		return nil, errors.New("contact_not_found", "contact does not exist")
+	}
+
+	history, err := s.scores.History(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "QUERY_FAILED", "failed to get score history")
+	}
+	return history, nil
+}
+
+// RecalculateContactScores scores every live contact as of at and records
+// the scores as the day's history. Rerunning a day replaces its scores.
+func (s *ContactServiceV2) RecalculateContactScores(ctx context.Context, at time.Time) (*types.ContactScoreRun, error) {
+	if s.scores == nil {
+		return nil, errScoringUnavailable
+	}
+
+	run := &types.ContactScoreRun{}
+	weights := map[uuid.UUID]*types.ContactScoreWeights{}
+	after := uuid.Nil
+	for {
+		batch, err := s.scores.SignalsBatch(ctx, after, contactScoreBatchSize, at, ContactScoreWindow)
+		if err != nil {
+			return run, err
+		}
+		if len(batch) == 0 {
+			return run, nil
+		}
+
+		snapshots := make([]types.ContactScoreSnapshot, 0, len(batch))
+		for _, signals := range batch {
+			orgWeights, ok := weights[signals.OrganizationID]
+			if !ok {
+				orgWeights, err = s.scores.Weights(ctx, signals.OrganizationID)
+				if err != nil {
+					return run, err
+				}
+				weights[signals.OrganizationID] = orgWeights
+			}
+			snapshots = append(snapshots, signals.Score(*orgWeights, ContactScoreWindow))
+		}
+		if err := s.scores.SaveSnapshots(ctx, snapshots); err != nil {
+			return run, err
+		}
+
+		run.ScoredOn = snapshots[0].ScoredOn
+		run.Scored += len(snapshots)
+		after = batch[len(batch)-1].ContactID
+	}
+}
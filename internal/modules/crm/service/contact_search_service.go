@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/crm/errors"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+// errSearchUnavailable is returned when the service has no search repository
+var errSearchUnavailable = errors.NewWithStatus("SERVICE_UNAVAILABLE", "contact search is not available", http.StatusServiceUnavailable)
+
+// SetSearch enables advanced contact search, with queries over the custom
+// fields the source defines
+func (s *ContactServiceV2) SetSearch(search types.ContactSearchRepository, customFields CustomFieldSource) {
+	s.search = search
+	s.customFields = customFields
+}
+
+// AdvancedSearchContacts searches contacts by text, tags, engagement score,
+// last contact and a boolean query over their fields, returning a page of
+// contacts and the total
+func (s *ContactServiceV2) AdvancedSearchContacts(ctx context.Context, filter types.AdvancedContactFilter) ([]*types.Contact, int, error) {
+	// Validate filter
+	if err := s.validateAdvancedContactFilter(ctx, &filter); err != nil {
+		return nil, 0, err
+	}
+
+	// Check organization access
+	if err := s.GetAuthService().CheckOrganizationAccess(ctx, filter.OrganizationID); err != nil {
+		return nil, 0, errors.ErrOrganizationAccess
+	}
+	if s.search == nil {
+		return nil, 0, errSearchUnavailable
+	}
+
+	contacts, err := s.search.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "SEARCH_FAILED", "failed to execute search query")
+	}
+	count, err := s.search.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "COUNT_FAILED", "failed to get search count")
+	}
+
+	return contacts, count, nil
+}
+
+// validateAdvancedContactFilter validates the advanced contact filter,
+// defaults its page and compiles its query
+func (s *ContactServiceV2) validateAdvancedContactFilter(ctx context.Context, filter *types.AdvancedContactFilter) error {
+	if filter.OrganizationID == uuid.Nil {
+		return errors.New("organization_id_required", "organization_id is required")
+	}
+	filter.Paginate()
+
+	for _, tag := range filter.Tags {
+		if _, err := uuid.Parse(tag); err != nil {
+			return errors.New("INVALID_INPUT", "tags must be tag IDs")
+		}
+	}
+
+	if filter.Query.IsEmpty() {
+		return nil
+	}
+	var defs []customfields.Definition
+	if s.customFields != nil {
+		var err error
+		if defs, err = s.customFields.Definitions(ctx, filter.OrganizationID, "contact"); err != nil {
+			return errors.Wrap(err, "SEARCH_FAILED", "failed to load custom fields")
+		}
+	}
+	condition, err := query.Compile(*filter.Query, types.ContactQuerySchema, defs)
+	if err != nil {
+		return errors.WrapWithStatus(err, "INVALID_QUERY", err.Error(), http.StatusBadRequest)
+	}
+	filter.Condition = condition
+	return nil
+}
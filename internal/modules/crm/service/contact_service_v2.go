@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"regexp"
 	"sort"
-	"strings"
 	"time"
 
 	"database/sql"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
 	"github.com/KevTiv/alieze-erp/pkg/crm/base"
 	"github.com/KevTiv/alieze-erp/pkg/crm/errors"
 	"github.com/KevTiv/alieze-erp/pkg/crm/validation"
@@ -46,9 +46,20 @@ type ContactUpdateRequest struct {
 	CountryID  *uuid.UUID `json:"country_id,omitempty"`
 }
 
+// DashboardCacheTTL is how long CRM and activity dashboards are served
+// from the cache when no contact or activity change invalidates them
+const DashboardCacheTTL = 5 * time.Minute
+
 // ContactServiceV2 implements standardized contact service
 type ContactServiceV2 struct {
 	*base.CRUDService[types.Contact, ContactRequest, ContactUpdateRequest, types.ContactFilter]
+	scores types.ContactScoreRepository
+	search types.ContactSearchRepository
+	// customFields loads the contact custom fields search queries may filter on
+	customFields CustomFieldSource
+	// dashboards caches the CRM and activity dashboards; nil computes them
+	// on every request
+	dashboards *cache.OrgCache
 }
 
 // NewContactServiceV2 creates a new standardized contact service
@@ -335,25 +346,7 @@ func (s *ContactServiceV2) applyContactUpdate(existing *types.Contact, req Conta
 }
 
 func (s *ContactServiceV2) getContactChanges(existing, updated *types.Contact) map[string]interface{} {
-	changes := make(map[string]interface{})
-
-	if existing.Name != updated.Name {
-		changes["name"] = map[string]string{"old": existing.Name, "new": updated.Name}
-	}
-	if (existing.Email == nil) != (updated.Email == nil) || (existing.Email != nil && updated.Email != nil && *existing.Email != *updated.Email) {
-		changes["email"] = map[string]interface{}{"old": existing.Email, "new": updated.Email}
-	}
-	if (existing.Phone == nil) != (updated.Phone == nil) || (existing.Phone != nil && updated.Phone != nil && *existing.Phone != *updated.Phone) {
-		changes["phone"] = map[string]interface{}{"old": existing.Phone, "new": updated.Phone}
-	}
-	if existing.IsCustomer != updated.IsCustomer {
-		changes["is_customer"] = map[string]bool{"old": existing.IsCustomer, "new": updated.IsCustomer}
-	}
-	if existing.IsVendor != updated.IsVendor {
-		changes["is_vendor"] = map[string]bool{"old": existing.IsVendor, "new": updated.IsVendor}
-	}
-
-	return changes
+	return audit.ChangeMap(audit.Diff(existing, updated, "updated_at", "updated_by"))
 }
 
 // CreateRelationship creates a relationship between contacts
@@ -495,44 +488,6 @@ func (s *ContactServiceV2) AddToSegments(ctx context.Context, orgID uuid.UUID, c
 	return nil
 }
 
-// CalculateContactScore calculates engagement and lead scores for a contact
-func (s *ContactServiceV2) CalculateContactScore(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID) (*types.ContactScore, error) {
-	// Validate organization access
-	if err := s.GetAuthService().CheckOrganizationAccess(ctx, orgID); err != nil {
-		return nil, errors.ErrOrganizationAccess
-	}
-
-	// Check if contact exists
-	exists, err := s.GetRepository().(interface {
-		ContactExists(context.Context, uuid.UUID, uuid.UUID) (bool, error)
-	}).ContactExists(ctx, orgID, contactID)
-	if err != nil {
-		return nil, errors.Wrap(err, "VALIDATION_FAILED", "failed to check contact existence")
-	}
-	if !exists {
-		return nil, errors.New("contact_not_found", "contact does not exist")
-	}
-
-	// For now, return a basic score calculation
-	// In a real implementation, this would analyze contact activity, engagement, etc.
-	score := &types.ContactScore{
-		EngagementScore: 50, // Placeholder
-		LeadScore:       60, // Placeholder
-		EngagementFactors: map[string]interface{}{
-			"recent_activity": "medium",
-			"response_rate":   "good",
-		},
-		LeadFactors: map[string]interface{}{
-			"fit":      "good",
-			"interest": "medium",
-			"budget":   "unknown",
-		},
-		LastUpdated: time.Now(),
-	}
-
-	return score, nil
-}
-
 // BulkCreateContacts creates multiple contacts in a single operation
 func (s *ContactServiceV2) BulkCreateContacts(ctx context.Context, requests []ContactRequest) ([]*types.Contact, []error) {
 	var results []*types.Contact
@@ -585,166 +540,6 @@ func (s *ContactServiceV2) BulkCreateContacts(ctx context.Context, requests []Co
 	return results, errs
 }
 
-// AdvancedSearchContacts performs advanced search with multiple criteria
-func (s *ContactServiceV2) AdvancedSearchContacts(ctx context.Context, filter types.AdvancedContactFilter) ([]*types.Contact, int, error) {
-	// Validate filter
-	if err := s.validateAdvancedContactFilter(filter); err != nil {
-		return nil, 0, err
-	}
-
-	// Check organization access
-	if err := s.GetAuthService().CheckOrganizationAccess(ctx, filter.OrganizationID); err != nil {
-		return nil, 0, errors.ErrOrganizationAccess
-	}
-
-	// Build query based on filter criteria
-	query, args := s.buildAdvancedSearchQuery(filter)
-
-	// Execute query
-	rows, err := s.GetRepository().(interface {
-		QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
-	}).QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "SEARCH_FAILED", "failed to execute search query")
-	}
-	defer rows.Close()
-
-	var contacts []*types.Contact
-	for rows.Next() {
-		var contact types.Contact
-		if err := rows.Scan(
-			&contact.ID,
-			&contact.OrganizationID,
-			&contact.Name,
-			&contact.Email,
-			&contact.Phone,
-			&contact.IsCustomer,
-			&contact.IsVendor,
-			&contact.Street,
-			&contact.City,
-			&contact.StateID,
-			&contact.CountryID,
-			&contact.CreatedAt,
-			&contact.UpdatedAt,
-			&contact.DeletedAt,
-		); err != nil {
-			return nil, 0, errors.Wrap(err, "SCAN_FAILED", "failed to scan contact")
-		}
-		contacts = append(contacts, &contact)
-	}
-
-	// Get total count
-	count, err := s.getAdvancedSearchCount(ctx, filter)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "COUNT_FAILED", "failed to get search count")
-	}
-
-	return contacts, count, nil
-}
-
-// validateAdvancedContactFilter validates the advanced contact filter
-func (s *ContactServiceV2) validateAdvancedContactFilter(filter types.AdvancedContactFilter) error {
-	if filter.OrganizationID == uuid.Nil {
-		return errors.New("organization_id_required", "organization_id is required")
-	}
-
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-
-	if filter.PageSize <= 0 || filter.PageSize > 100 {
-		filter.PageSize = 20
-	}
-
-	return nil
-}
-
-// buildAdvancedSearchQuery builds the SQL query for advanced search
-func (s *ContactServiceV2) buildAdvancedSearchQuery(filter types.AdvancedContactFilter) (string, []interface{}) {
-	query := `SELECT id, organization_id, name, email, phone, is_customer, is_vendor, street, city, state_id, country_id, created_at, updated_at, deleted_at
-		FROM contacts
-		WHERE organization_id = $1 AND deleted_at IS NULL`
-
-	args := []interface{}{filter.OrganizationID}
-	argIndex := 2
-
-	// Add search query condition
-	if filter.SearchQuery != "" {
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR email ILIKE $%d OR phone ILIKE $%d)", argIndex, argIndex+1, argIndex+2)
-		searchParam := "%" + filter.SearchQuery + "%"
-		args = append(args, searchParam, searchParam, searchParam)
-		argIndex += 3
-	}
-
-	// Add tag conditions (would require tags table)
-	if len(filter.Tags) > 0 {
-		// Implementation would join with contact_tags table
-	}
-
-	// Add score range condition
-	if filter.ScoreRange.Min > 0 || filter.ScoreRange.Max > 0 {
-		if filter.ScoreRange.Min > 0 && filter.ScoreRange.Max > 0 {
-			query += fmt.Sprintf(" AND engagement_score BETWEEN $%d AND $%d", argIndex, argIndex+1)
-			args = append(args, filter.ScoreRange.Min, filter.ScoreRange.Max)
-			argIndex += 2
-		} else if filter.ScoreRange.Min > 0 {
-			query += fmt.Sprintf(" AND engagement_score >= $%d", argIndex)
-			args = append(args, filter.ScoreRange.Min)
-			argIndex++
-		} else if filter.ScoreRange.Max > 0 {
-			query += fmt.Sprintf(" AND engagement_score <= $%d", argIndex)
-			args = append(args, filter.ScoreRange.Max)
-			argIndex++
-		}
-	}
-
-	// Add last contacted condition
-	if !filter.LastContacted.From.IsZero() || !filter.LastContacted.To.IsZero() {
-		if !filter.LastContacted.From.IsZero() && !filter.LastContacted.To.IsZero() {
-			query += fmt.Sprintf(" AND last_contacted_at BETWEEN $%d AND $%d", argIndex, argIndex+1)
-			args = append(args, filter.LastContacted.From, filter.LastContacted.To)
-			argIndex += 2
-		} else if !filter.LastContacted.From.IsZero() {
-			query += fmt.Sprintf(" AND last_contacted_at >= $%d", argIndex)
-			args = append(args, filter.LastContacted.From)
-			argIndex++
-		} else if !filter.LastContacted.To.IsZero() {
-			query += fmt.Sprintf(" AND last_contacted_at <= $%d", argIndex)
-			args = append(args, filter.LastContacted.To)
-			argIndex++
-		}
-	}
-
-	// Add pagination
-	query += fmt.Sprintf(" ORDER BY updated_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	offset := (filter.Page - 1) * filter.PageSize
-	args = append(args, filter.PageSize, offset)
-
-	return query, args
-}
-
-// getAdvancedSearchCount gets the total count for advanced search
-func (s *ContactServiceV2) getAdvancedSearchCount(ctx context.Context, filter types.AdvancedContactFilter) (int, error) {
-	query, args := s.buildAdvancedSearchQuery(filter)
-	// Replace SELECT with COUNT
-	countQuery := strings.Replace(query,
-		"SELECT id, organization_id, name, email, phone, is_customer, is_vendor, street, city, state_id, country_id, created_at, updated_at, deleted_at",
-		"SELECT COUNT(*)", 1)
-	// Remove ORDER BY, LIMIT, OFFSET
-	countQuery = regexCount.ReplaceAllString(countQuery, "")
-
-	var count int
-	if err := s.GetRepository().(interface {
-		QueryRowContext(context.Context, string, ...interface{}) *sql.Row
-	}).QueryRowContext(ctx, countQuery, args...).Scan(&count); err != nil {
-		return 0, err
-	}
-
-	return count, nil
-}
-
-var regexCount = regexp.MustCompile(`ORDER BY.*$`)
-
 // GetCRMDashboard retrieves comprehensive CRM dashboard data
 func (s *ContactServiceV2) GetCRMDashboard(ctx context.Context, orgID uuid.UUID, timeRange string) (*types.CRMDashboard, error) {
 	// Parse time range
@@ -754,10 +549,10 @@ func (s *ContactServiceV2) GetCRMDashboard(ctx context.Context, orgID uuid.UUID,
 	}
 
 	// Check cache first
-	if cachedData, found := s.getCachedDashboard(ctx, orgID, timeRange, ""); found {
-		if dashboard, ok := cachedData.(*types.CRMDashboard); ok {
-			return dashboard, nil
-		}
+	cacheKey := "crm:" + timeRange
+	var cached types.CRMDashboard
+	if s.getCachedDashboard(ctx, orgID, cacheKey, &cached) {
+		return &cached, nil
 	}
 
 	dashboard := &types.CRMDashboard{
@@ -801,7 +596,7 @@ func (s *ContactServiceV2) GetCRMDashboard(ctx context.Context, orgID uuid.UUID,
 	dashboard.RecentActivities = activities
 
 	// Cache the dashboard data
-	s.setDashboardCache(ctx, orgID, timeRange, "", dashboard)
+	s.setDashboardCache(ctx, orgID, cacheKey, dashboard)
 
 	return dashboard, nil
 }
@@ -815,11 +610,10 @@ func (s *ContactServiceV2) GetActivityDashboard(ctx context.Context, orgID uuid.
 	}
 
 	// Check cache first
-	cacheKey := fmt.Sprintf("%s_%s", timeRange, contactType)
-	if cachedData, found := s.getCachedDashboard(ctx, orgID, timeRange, cacheKey); found {
-		if dashboard, ok := cachedData.(*types.ActivityDashboard); ok {
-			return dashboard, nil
-		}
+	cacheKey := fmt.Sprintf("activity:%s:%s", timeRange, contactType)
+	var cached types.ActivityDashboard
+	if s.getCachedDashboard(ctx, orgID, cacheKey, &cached) {
+		return &cached, nil
 	}
 
 	dashboard := &types.ActivityDashboard{
@@ -851,7 +645,7 @@ func (s *ContactServiceV2) GetActivityDashboard(ctx context.Context, orgID uuid.
 	dashboard.ContactEngagement = engagement
 
 	// Cache the dashboard data
-	s.setDashboardCache(ctx, orgID, timeRange, cacheKey, dashboard)
+	s.setDashboardCache(ctx, orgID, cacheKey, dashboard)
 
 	return dashboard, nil
 }
@@ -1178,17 +972,34 @@ func getRecommendedAction(engagement types.ContactEngagement) string {
 	return "Maintain engagement - regular check-ins"
 }
 
-// getCachedDashboard retrieves cached dashboard data
-func (s *ContactServiceV2) getCachedDashboard(ctx context.Context, orgID uuid.UUID, timeRange string, cacheKey string) (interface{}, bool) {
-	// In a real implementation, this would check a cache (Redis, in-memory, etc.)
-	// For now, return false to indicate no cache
-	return nil, false
+// SetDashboardCache sets the cache CRM and activity dashboards are served
+// from
+func (s *ContactServiceV2) SetDashboardCache(dashboards *cache.OrgCache) {
+	s.dashboards = dashboards
+}
+
+// InvalidateDashboards drops the organization's cached dashboards, so that
+// they are computed again with its latest contacts and activities
+func (s *ContactServiceV2) InvalidateDashboards(ctx context.Context, orgID uuid.UUID) {
+	if s.dashboards != nil {
+		s.dashboards.Invalidate(ctx, orgID)
+	}
+}
+
+// getCachedDashboard decodes the organization's cached dashboard into
+// dashboard and reports whether there was one
+func (s *ContactServiceV2) getCachedDashboard(ctx context.Context, orgID uuid.UUID, cacheKey string, dashboard interface{}) bool {
+	if s.dashboards == nil {
+		return false
+	}
+	return s.dashboards.Get(ctx, orgID, cacheKey, dashboard)
 }
 
 // setDashboardCache stores dashboard data in cache
-func (s *ContactServiceV2) setDashboardCache(ctx context.Context, orgID uuid.UUID, timeRange string, cacheKey string, data interface{}) {
-	// In a real implementation, this would store in cache with TTL
-	// For now, do nothing
+func (s *ContactServiceV2) setDashboardCache(ctx context.Context, orgID uuid.UUID, cacheKey string, dashboard interface{}) {
+	if s.dashboards != nil {
+		s.dashboards.Set(ctx, orgID, cacheKey, dashboard)
+	}
 }
 
 // Helper function to safely dereference string pointers
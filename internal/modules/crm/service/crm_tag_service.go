@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxCRMTagName bounds tag names to the crm_tags.name column
+	maxCRMTagName = 100
+	// DefaultCRMTagLimit and MaxCRMTagLimit page the tag list
+	DefaultCRMTagLimit = 100
+	MaxCRMTagLimit     = 500
+)
+
+// crmTagTargetPermissions are the permissions needed to tag each kind of
+// record, the same as editing it
+var crmTagTargetPermissions = map[types.CRMTagTarget]string{
+	types.CRMTagTargetLeads:    "crm:leads:update",
+	types.CRMTagTargetContacts: "crm:contacts:update",
+}
+
+// CRMTagService manages the organization's tags and applies them to leads
+// and contacts
+type CRMTagService struct {
+	repo        types.CRMTagRepository
+	authService auth.LegacyAuthService
+}
+
+func NewCRMTagService(repo types.CRMTagRepository, authService auth.LegacyAuthService) *CRMTagService {
+	return &CRMTagService{
+		repo:        repo,
+		authService: authService,
+	}
+}
+
+// checkCRMTag trims the name and validates the name and color
+func checkCRMTag(tag *types.CRMTag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrInvalidCRMTag)
+	}
+	if utf8.RuneCountInString(tag.Name) > maxCRMTagName {
+		return fmt.Errorf("%w: name must be at most %d characters", types.ErrInvalidCRMTag, maxCRMTagName)
+	}
+	if tag.Color < 0 {
+		return fmt.Errorf("%w: color must not be negative", types.ErrInvalidCRMTag)
+	}
+	return nil
+}
+
+// ListTags returns the organization's tags with how many leads and contacts
+// carry each
+func (s *CRMTagService) ListTags(ctx context.Context, filter types.CRMTagFilter) ([]types.CRMTag, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:tags:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultCRMTagLimit
+	}
+	if filter.Limit > MaxCRMTagLimit {
+		filter.Limit = MaxCRMTagLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.FindAll(ctx, filter)
+}
+
+func (s *CRMTagService) GetTag(ctx context.Context, orgID, id uuid.UUID) (*types.CRMTag, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:tags:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindByID(ctx, orgID, id)
+}
+
+func (s *CRMTagService) CreateTag(ctx context.Context, orgID uuid.UUID, req types.CRMTagCreateRequest) (*types.CRMTag, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:tags:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	tag := types.CRMTag{OrganizationID: orgID, Name: req.Name, Color: req.Color}
+	if err := checkCRMTag(&tag); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, tag)
+}
+
+// UpdateTag renames or recolors a tag. Omitted fields are left unchanged.
+func (s *CRMTagService) UpdateTag(ctx context.Context, orgID, id uuid.UUID, req types.CRMTagUpdateRequest) (*types.CRMTag, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:tags:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	tag, err := s.repo.FindByID(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		tag.Name = *req.Name
+	}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+	if err := checkCRMTag(tag); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *tag)
+}
+
+// DeleteTag deletes a tag and removes it from every lead and contact
+func (s *CRMTagService) DeleteTag(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:tags:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.Delete(ctx, orgID, id)
+}
+
+// AddTags tags a lead or contact and returns the tags it carries. Every tag
+// must be one of the organization's.
+func (s *CRMTagService) AddTags(ctx context.Context, orgID uuid.UUID, target types.CRMTagTarget, recordID uuid.UUID, tagIDs []uuid.UUID) ([]uuid.UUID, error) {
+	permission, ok := crmTagTargetPermissions[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown target %q", types.ErrInvalidCRMTag, target)
+	}
+	if err := s.authService.CheckPermission(ctx, permission); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if len(tagIDs) == 0 {
+		return nil, fmt.Errorf("%w: tag_ids is required", types.ErrInvalidCRMTag)
+	}
+
+	missing, err := s.repo.MissingIDs(ctx, orgID, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, id := range missing {
+			ids[i] = id.String()
+		}
+		return nil, fmt.Errorf("%w: unknown tags %s", types.ErrInvalidCRMTag, strings.Join(ids, ", "))
+	}
+	return s.repo.AddToRecord(ctx, orgID, target, recordID, tagIDs)
+}
+
+// RemoveTag untags a lead or contact and returns the tags it still carries
+func (s *CRMTagService) RemoveTag(ctx context.Context, orgID uuid.UUID, target types.CRMTagTarget, recordID, tagID uuid.UUID) ([]uuid.UUID, error) {
+	permission, ok := crmTagTargetPermissions[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown target %q", types.ErrInvalidCRMTag, target)
+	}
+	if err := s.authService.CheckPermission(ctx, permission); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.RemoveFromRecord(ctx, orgID, target, recordID, tagID)
+}
@@ -2,14 +2,29 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
 
 	"github.com/google/uuid"
 )
 
+// FiscalPeriods resolves fiscal period parameters in the organization's
+// fiscal calendar
+type FiscalPeriods interface {
+	Calendar(ctx context.Context, orgID uuid.UUID) (*fiscal.Calendar, error)
+	Resolve(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error)
+}
+
+// SetFiscalPeriods enables revenue analytics by fiscal period
+func (s *LeadService) SetFiscalPeriods(periods FiscalPeriods) {
+	s.periods = periods
+}
+
 // GetLeadPipelineValue calculates the total pipeline value
 func (s *LeadService) GetLeadPipelineValue(ctx context.Context, orgID uuid.UUID) (float64, error) {
 	// Calculate pipeline value by summing expected revenue of all active leads
@@ -37,7 +52,7 @@ func (s *LeadService) GetLeadPipelineValue(ctx context.Context, orgID uuid.UUID)
 // GetLeadPipelineValueByStage calculates pipeline value by stage
 func (s *LeadService) GetLeadPipelineValueByStage(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]float64, error) {
 	// Get counts by stage first (currently unused but kept for future reference)
-	counts, err := s.repo.CountByStage(ctx)
+	counts, err := s.repo.CountByStage(ctx, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lead counts by stage: %w", err)
 	}
@@ -321,29 +336,17 @@ func (s *LeadService) GetLeadsByTag(ctx context.Context, orgID uuid.UUID, tagID
 		return nil, fmt.Errorf("invalid tag ID")
 	}
 
-	// Note: This is a simplified approach. In a real implementation, we'd need
-	// to query a separate lead_tags table or use JSON functions to search within tag_ids
 	filter := types.LeadFilter{
 		OrganizationID: orgID,
+		TagIDs:         []uuid.UUID{tagID},
 	}
 
 	leads, err := s.repo.FindAll(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get leads for tag filtering: %w", err)
-	}
-
-	// Filter in memory (not ideal for large datasets)
-	var filteredLeads []*types.Lead
-	for _, lead := range leads {
-		for _, id := range lead.TagIDs {
-			if id == tagID {
-				filteredLeads = append(filteredLeads, lead)
-				break
-			}
-		}
+		return nil, fmt.Errorf("failed to get leads by tag: %w", err)
 	}
 
-	return filteredLeads, nil
+	return leads, nil
 }
 
 // GetLeadsByCompany retrieves leads by company
@@ -566,6 +569,177 @@ func (s *LeadService) GetLeadAverageRecurringRevenue(ctx context.Context, orgID
 	return totalRecurringRevenue / float64(count), nil
 }
 
+// GetRecurringRevenueReport calculates the MRR and ARR of the active leads
+// that were not lost, in total and by stage, team, close month and fiscal
+// close period, from their recurring revenue and plan. A lead closes on its
+// close date, or on its deadline while open. teamID, when set, limits the
+// report to that team's pipeline, and period, such as FY2025-Q2, to the
+// leads closing in that fiscal period.
+func (s *LeadService) GetRecurringRevenueReport(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID, period string) (*types.RecurringRevenueReport, error) {
+	report := &types.RecurringRevenueReport{}
+	var cal *fiscal.Calendar
+	var closing *fiscal.Period
+	if s.periods != nil {
+		var err error
+		if cal, err = s.periods.Calendar(ctx, orgID); err != nil {
+			return nil, fmt.Errorf("failed to get fiscal calendar: %w", err)
+		}
+	}
+	if period != "" {
+		if s.periods == nil {
+			return nil, errors.New("fiscal periods are not available")
+		}
+		p, err := s.periods.Resolve(ctx, orgID, period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve fiscal period: %w", err)
+		}
+		closing = &p
+		report.FiscalPeriod = p.Label
+	}
+
+	filter := types.LeadFilter{
+		OrganizationID: orgID,
+		TeamID:         teamID,
+	}
+	active := true
+	filter.Active = &active
+
+	leads, err := s.repo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leads for recurring revenue report: %w", err)
+	}
+
+	byStage := make(map[uuid.UUID]*types.StageRecurringRevenue)
+	byTeam := make(map[uuid.UUID]*types.TeamRecurringRevenue)
+	byMonth := make(map[string]*types.MonthRecurringRevenue)
+	byPeriod := make(map[string]*types.FiscalPeriodRecurringRevenue)
+	for _, lead := range leads {
+		if lead.Status == types.LeadStatusLost || (lead.WonStatus != nil && *lead.WonStatus == types.LeadWonStatusLost) {
+			continue
+		}
+		closes := lead.DateClosed
+		if closes == nil {
+			closes = lead.DateDeadline
+		}
+		if closing != nil && (closes == nil || !closing.Contains(closes.UTC())) {
+			continue
+		}
+		mrr, ok := lead.MonthlyRecurringRevenue()
+		if !ok {
+			if lead.RecurringRevenue != nil {
+				if report.UnknownPlans == nil {
+					report.UnknownPlans = make(map[string]int)
+				}
+				plan := ""
+				if lead.RecurringPlan != nil {
+					plan = *lead.RecurringPlan
+				}
+				report.UnknownPlans[plan]++
+			}
+			continue
+		}
+
+		report.Total.Add(mrr, lead.Probability)
+
+		// uuid.Nil keys the leads without a stage or team
+		stageKey := uuid.Nil
+		if lead.StageID != nil {
+			stageKey = *lead.StageID
+		}
+		if byStage[stageKey] == nil {
+			byStage[stageKey] = &types.StageRecurringRevenue{StageID: lead.StageID}
+		}
+		byStage[stageKey].Add(mrr, lead.Probability)
+
+		teamKey := uuid.Nil
+		if lead.TeamID != nil {
+			teamKey = *lead.TeamID
+		}
+		if byTeam[teamKey] == nil {
+			byTeam[teamKey] = &types.TeamRecurringRevenue{TeamID: lead.TeamID}
+		}
+		byTeam[teamKey].Add(mrr, lead.Probability)
+
+		month := ""
+		if closes != nil {
+			month = closes.UTC().Format("2006-01")
+		}
+		if byMonth[month] == nil {
+			byMonth[month] = &types.MonthRecurringRevenue{Month: month}
+		}
+		byMonth[month].Add(mrr, lead.Probability)
+
+		if cal != nil {
+			label := ""
+			if closes != nil {
+				label = cal.PeriodOf(closes.UTC()).Label
+			}
+			if byPeriod[label] == nil {
+				byPeriod[label] = &types.FiscalPeriodRecurringRevenue{Period: label}
+			}
+			byPeriod[label].Add(mrr, lead.Probability)
+		}
+	}
+
+	// Stages and teams are listed largest first, months and periods in
+	// order with the leads without a close date last
+	report.ByStage = make([]types.StageRecurringRevenue, 0, len(byStage))
+	for _, group := range byStage {
+		report.ByStage = append(report.ByStage, *group)
+	}
+	sort.Slice(report.ByStage, func(i, j int) bool {
+		return byLargerMRR(report.ByStage[i].RecurringRevenue, report.ByStage[j].RecurringRevenue,
+			report.ByStage[i].StageID, report.ByStage[j].StageID)
+	})
+
+	report.ByTeam = make([]types.TeamRecurringRevenue, 0, len(byTeam))
+	for _, group := range byTeam {
+		report.ByTeam = append(report.ByTeam, *group)
+	}
+	sort.Slice(report.ByTeam, func(i, j int) bool {
+		return byLargerMRR(report.ByTeam[i].RecurringRevenue, report.ByTeam[j].RecurringRevenue,
+			report.ByTeam[i].TeamID, report.ByTeam[j].TeamID)
+	})
+
+	report.ByCloseMonth = make([]types.MonthRecurringRevenue, 0, len(byMonth))
+	for _, group := range byMonth {
+		report.ByCloseMonth = append(report.ByCloseMonth, *group)
+	}
+	sort.Slice(report.ByCloseMonth, func(i, j int) bool {
+		return closesBefore(report.ByCloseMonth[i].Month, report.ByCloseMonth[j].Month)
+	})
+
+	for _, group := range byPeriod {
+		report.ByClosePeriod = append(report.ByClosePeriod, *group)
+	}
+	sort.Slice(report.ByClosePeriod, func(i, j int) bool {
+		return closesBefore(report.ByClosePeriod[i].Period, report.ByClosePeriod[j].Period)
+	})
+
+	return report, nil
+}
+
+// closesBefore orders close months and fiscal periods, whose keys sort as
+// strings, with the empty key of leads without a close date last
+func closesBefore(a, b string) bool {
+	if a == "" || b == "" {
+		return b == "" && a != ""
+	}
+	return a < b
+}
+
+// byLargerMRR orders recurring revenue groups by MRR, largest first, then
+// by ID with the group without one last
+func byLargerMRR(a, b types.RecurringRevenue, aID, bID *uuid.UUID) bool {
+	if a.MRR != b.MRR {
+		return a.MRR > b.MRR
+	}
+	if aID == nil || bID == nil {
+		return bID == nil && aID != nil
+	}
+	return aID.String() < bID.String()
+}
+
 // GetLeadsByContact retrieves leads by contact
 func (s *LeadService) GetLeadsByContact(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID) ([]*types.Lead, error) {
 	if contactID == uuid.Nil {
@@ -779,36 +953,23 @@ func (s *LeadService) GetLeadsByColor(ctx context.Context, orgID uuid.UUID, colo
 	return leads, nil
 }
 
-// GetLeadsByStatus retrieves leads by status
-func (s *LeadService) GetLeadsByStatus(ctx context.Context, orgID uuid.UUID, status string) ([]*types.Lead, error) {
-	if status == "" {
-		return nil, fmt.Errorf("status cannot be empty")
+// GetLeadsByStatus retrieves leads by lifecycle status
+func (s *LeadService) GetLeadsByStatus(ctx context.Context, orgID uuid.UUID, status types.LeadStatus) ([]*types.Lead, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid lead status: %s", status)
 	}
 
 	filter := types.LeadFilter{
 		OrganizationID: orgID,
+		Status:         &status,
 	}
 
 	leads, err := s.repo.FindAll(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get leads for status filtering: %w", err)
+		return nil, fmt.Errorf("failed to get leads by status: %w", err)
 	}
 
-	// Filter in memory for status (simplified approach)
-	var filteredLeads []*types.Lead
-	for _, lead := range leads {
-		// This is a simplified approach - in a real implementation, you'd need
-		// to define what "status" means for leads (e.g., won/lost, active/inactive, etc.)
-		if lead.WonStatus != nil && string(*lead.WonStatus) == status {
-			filteredLeads = append(filteredLeads, lead)
-		} else if status == "active" && lead.Active {
-			filteredLeads = append(filteredLeads, lead)
-		} else if status == "inactive" && !lead.Active {
-			filteredLeads = append(filteredLeads, lead)
-		}
-	}
-
-	return filteredLeads, nil
+	return leads, nil
 }
 
 // GetLeadsByActiveStatus retrieves leads by active status
@@ -885,7 +1046,7 @@ func (s *LeadService) GetLeadsByWonStatus(ctx context.Context, orgID uuid.UUID,
 
 // CountLeadsByStage counts leads by stage
 func (s *LeadService) CountLeadsByStage(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]int, error) {
-	counts, err := s.repo.CountByStage(ctx)
+	counts, err := s.repo.CountByStage(ctx, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count leads by stage: %w", err)
 	}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KevTiv/alieze-erp/pkg/attachments"
+
+	"github.com/google/uuid"
+)
+
+// leadAttachmentModel is the res_model of lead attachments
+const leadAttachmentModel = "leads"
+
+// LeadAttachmentUpload is a file to attach to a lead
+type LeadAttachmentUpload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Reader      io.Reader
+}
+
+// SetAttachments enables lead attachments
+func (s *LeadService) SetAttachments(attachmentService *attachments.Service) {
+	s.attachments = attachmentService
+}
+
+// AttachmentPolicy returns the limits uploads are checked against
+func (s *LeadService) AttachmentPolicy() attachments.Policy {
+	if s.attachments == nil {
+		return attachments.DefaultPolicy()
+	}
+	return s.attachments.Policy()
+}
+
+// leadForAttachments checks the permission and that the lead is the
+// organization's
+func (s *LeadService) leadForAttachments(ctx context.Context, orgID, leadID uuid.UUID, permission string) (string, error) {
+	if err := s.authService.CheckPermission(ctx, permission); err != nil {
+		return "", fmt.Errorf("permission denied: %w", err)
+	}
+	if s.attachments == nil {
+		return "", errors.New("lead attachments are not available")
+	}
+	lead, err := s.GetLead(ctx, orgID, leadID)
+	if err != nil {
+		return "", err
+	}
+	return lead.Name, nil
+}
+
+// UploadLeadAttachment attaches a file to a lead
+func (s *LeadService) UploadLeadAttachment(ctx context.Context, orgID, userID, leadID uuid.UUID, upload LeadAttachmentUpload) (*attachments.Attachment, error) {
+	leadName, err := s.leadForAttachments(ctx, orgID, leadID, "crm:leads:update")
+	if err != nil {
+		return nil, err
+	}
+	return s.attachments.Upload(ctx, attachments.Upload{
+		OrganizationID: orgID,
+		ResModel:       leadAttachmentModel,
+		ResID:          leadID,
+		ResName:        leadName,
+		FileName:       upload.FileName,
+		ContentType:    upload.ContentType,
+		Size:           upload.Size,
+		Reader:         upload.Reader,
+		CreatedBy:      &userID,
+	})
+}
+
+// ListLeadAttachments returns a lead's attachments, newest first, with
+// download URLs
+func (s *LeadService) ListLeadAttachments(ctx context.Context, orgID, leadID uuid.UUID) ([]attachments.Attachment, error) {
+	if _, err := s.leadForAttachments(ctx, orgID, leadID, "crm:leads:read"); err != nil {
+		return nil, err
+	}
+	return s.attachments.List(ctx, orgID, leadAttachmentModel, leadID)
+}
+
+// GetLeadAttachment returns a lead's attachment with a fresh download URL
+func (s *LeadService) GetLeadAttachment(ctx context.Context, orgID, leadID, id uuid.UUID) (*attachments.Attachment, error) {
+	if _, err := s.leadForAttachments(ctx, orgID, leadID, "crm:leads:read"); err != nil {
+		return nil, err
+	}
+	return s.attachments.Get(ctx, orgID, leadAttachmentModel, leadID, id)
+}
+
+// DeleteLeadAttachment removes a lead's attachment and its file
+func (s *LeadService) DeleteLeadAttachment(ctx context.Context, orgID, leadID, id uuid.UUID) error {
+	if _, err := s.leadForAttachments(ctx, orgID, leadID, "crm:leads:update"); err != nil {
+		return err
+	}
+	return s.attachments.Delete(ctx, orgID, leadAttachmentModel, leadID, id)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+
+	"github.com/google/uuid"
+)
+
+// maxLeadAuditExportRange is the longest period one audit export covers
+const maxLeadAuditExportRange = 366 * 24 * time.Hour
+
+// leadAuditSkipped are the lead fields left out of its history: the update
+// stamps, and values that follow from another audited field
+var leadAuditSkipped = []string{"updated_at", "updated_by", "date_last_stage_update", "system_probability", "computed"}
+
+// SetAudit enables the field-level change history of leads
+func (s *LeadService) SetAudit(audit types.LeadAuditRepository) {
+	s.audit = audit
+}
+
+// recordLeadChanges stores the fields an update changed, if any
+func (s *LeadService) recordLeadChanges(ctx context.Context, before, after types.Lead) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	changes := audit.Diff(before, after, leadAuditSkipped...)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var changedBy *uuid.UUID
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		changedBy = &userID
+	}
+	entries, err := types.NewLeadAuditEntries(after.OrganizationID, after.ID, changes, types.LeadAuditUpdate, changedBy, after.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := s.audit.Record(ctx, entries); err != nil {
+		return fmt.Errorf("failed to record lead history: %w", err)
+	}
+	return nil
+}
+
+// GetLeadHistory returns the field changes of a lead, most recent first
+func (s *LeadService) GetLeadHistory(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadAuditEntry, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.audit == nil {
+		return nil, errors.New("lead history is not available")
+	}
+
+	if _, err := s.GetLead(ctx, orgID, leadID); err != nil {
+		return nil, err
+	}
+
+	return s.audit.FindByLead(ctx, orgID, leadID)
+}
+
+// ExportLeadAudit returns the organization's lead field changes in a period,
+// oldest first, up to MaxLeadAuditExport of them
+func (s *LeadService) ExportLeadAudit(ctx context.Context, filter types.LeadAuditFilter) ([]types.LeadAuditEntry, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_audit:export"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.audit == nil {
+		return nil, errors.New("lead history is not available")
+	}
+
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return nil, fmt.Errorf("%w: from and to are required", types.ErrInvalidLeadAuditFilter)
+	}
+	if !filter.To.After(filter.From) {
+		return nil, fmt.Errorf("%w: to must be after from", types.ErrInvalidLeadAuditFilter)
+	}
+	if filter.To.Sub(filter.From) > maxLeadAuditExportRange {
+		return nil, fmt.Errorf("%w: an export covers at most 366 days", types.ErrInvalidLeadAuditFilter)
+	}
+	if filter.Limit <= 0 || filter.Limit > types.MaxLeadAuditExport {
+		filter.Limit = types.MaxLeadAuditExport
+	}
+
+	return s.audit.Export(ctx, filter)
+}
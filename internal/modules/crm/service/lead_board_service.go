@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLeadBoardLimit is the number of cards per column of boards that
+	// set none
+	DefaultLeadBoardLimit = 20
+	// MaxLeadBoardLimit bounds the number of cards per column
+	MaxLeadBoardLimit = 100
+)
+
+// SetBoard enables the kanban lead board. The stages repository gives the
+// board its columns.
+func (s *LeadService) SetBoard(board types.LeadBoardRepository, stages types.LeadStageRepository) {
+	s.board = board
+	s.stageRepo = stages
+}
+
+// GetLeadBoard returns the organization's filtered leads grouped by stage:
+// a column per stage in pipeline order with the count and expected revenue
+// of all its leads and its first cards, or the cards after each cursor
+// given. Stages of other teams are left out when the filter names a team,
+// unless they hold leads; leads without a stage get a last column.
+func (s *LeadService) GetLeadBoard(ctx context.Context, orgID uuid.UUID, req types.LeadBoardRequest) (*types.LeadBoard, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.board == nil || s.stageRepo == nil {
+		return nil, errors.New("lead board is not available")
+	}
+
+	if req.Limit == 0 {
+		req.Limit = DefaultLeadBoardLimit
+	}
+	if req.Limit < 0 || req.Limit > MaxLeadBoardLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", types.ErrInvalidLeadBoard, MaxLeadBoardLimit)
+	}
+	offsets := make(map[uuid.UUID]int, len(req.Cursors))
+	for _, cursor := range req.Cursors {
+		stageID, offset, err := decodeLeadBoardCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := offsets[stageID]; ok {
+			return nil, fmt.Errorf("%w: more than one cursor for a column", types.ErrInvalidLeadBoard)
+		}
+		offsets[stageID] = offset
+	}
+
+	filter := req.Filter
+	filter.OrganizationID = orgID
+	if err := filter.ValidateSort(); err != nil {
+		return nil, err
+	}
+	if err := s.applyComputedFields(ctx, &filter, false); err != nil {
+		return nil, err
+	}
+	if err := s.applyCustomFieldFilters(ctx, &filter); err != nil {
+		return nil, err
+	}
+	if err := s.applyQuery(ctx, &filter); err != nil {
+		return nil, err
+	}
+
+	stages, err := s.stageRepo.FindAll(ctx, types.LeadStageFilter{OrganizationID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.board.Totals(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := s.board.Cards(ctx, filter, req.Limit, offsets)
+	if err != nil {
+		return nil, err
+	}
+	return buildLeadBoard(filter, stages, totals, cards, offsets), nil
+}
+
+// buildLeadBoard lays the totals and cards out in columns
+func buildLeadBoard(filter types.LeadFilter, stages []*types.LeadStage, totals []types.LeadBoardTotals, cards []*types.Lead, offsets map[uuid.UUID]int) *types.LeadBoard {
+	board := &types.LeadBoard{Columns: []types.LeadBoardColumn{}}
+	columns := make(map[uuid.UUID]*types.LeadBoardColumn)
+	var order []uuid.UUID
+	addColumn := func(key uuid.UUID, column types.LeadBoardColumn) {
+		column.Leads = []*types.Lead{}
+		columns[key] = &column
+		order = append(order, key)
+	}
+
+	for _, stage := range stages {
+		if filter.StageID != nil && *filter.StageID != stage.ID {
+			continue
+		}
+		if filter.TeamID != nil && stage.TeamID != nil && *stage.TeamID != *filter.TeamID {
+			continue
+		}
+		stageID := stage.ID
+		addColumn(stage.ID, types.LeadBoardColumn{
+			StageID:   &stageID,
+			StageName: stage.Name,
+			Sequence:  stage.Sequence,
+			Fold:      stage.Fold,
+			IsWon:     stage.IsWon,
+		})
+	}
+
+	// Leads can sit in a stage left out above; those without a stage come
+	// last
+	unstaged := false
+	for _, total := range totals {
+		if total.StageID == nil {
+			unstaged = true
+			continue
+		}
+		if _, ok := columns[*total.StageID]; !ok {
+			addColumn(*total.StageID, types.LeadBoardColumn{StageID: total.StageID})
+		}
+	}
+	if unstaged {
+		addColumn(uuid.Nil, types.LeadBoardColumn{})
+	}
+	for _, total := range totals {
+		key := uuid.Nil
+		if total.StageID != nil {
+			key = *total.StageID
+		}
+		columns[key].Count = total.Count
+		columns[key].ExpectedRevenue = total.ExpectedRevenue
+		board.Total += total.Count
+		board.ExpectedRevenue += total.ExpectedRevenue
+	}
+
+	for _, lead := range cards {
+		key := uuid.Nil
+		if lead.StageID != nil {
+			key = *lead.StageID
+		}
+		if column, ok := columns[key]; ok {
+			column.Leads = append(column.Leads, lead)
+		}
+	}
+
+	for _, key := range order {
+		column := columns[key]
+		if next := offsets[key] + len(column.Leads); len(column.Leads) > 0 && next < column.Count {
+			column.NextCursor = encodeLeadBoardCursor(key, next)
+		}
+		board.Columns = append(board.Columns, *column)
+	}
+	return board
+}
+
+// encodeLeadBoardCursor returns the opaque cursor of a column's cards after
+// offset
+func encodeLeadBoardCursor(stageID uuid.UUID, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(stageID.String() + ":" + strconv.Itoa(offset)))
+}
+
+// decodeLeadBoardCursor returns the column and offset of a cursor
+func decodeLeadBoardCursor(cursor string) (uuid.UUID, int, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", types.ErrInvalidLeadBoard)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, 0, invalid
+	}
+	stage, offset, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return uuid.Nil, 0, invalid
+	}
+	stageID, err := uuid.Parse(stage)
+	if err != nil {
+		return uuid.Nil, 0, invalid
+	}
+	n, err := strconv.Atoi(offset)
+	if err != nil || n < 0 {
+		return uuid.Nil, 0, invalid
+	}
+	return stageID, n, nil
+}
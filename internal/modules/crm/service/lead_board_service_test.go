@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestBuildLeadBoardGroupsLeadsByStage(t *testing.T) {
+	newID, wonID, otherTeamID, teamID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	otherTeam := uuid.New()
+	stages := []*types.LeadStage{
+		{ID: newID, Name: "New", Sequence: 1},
+		{ID: otherTeamID, Name: "Other team", Sequence: 2, TeamID: &otherTeam},
+		{ID: wonID, Name: "Won", Sequence: 3, IsWon: true, Fold: true},
+	}
+	totals := []types.LeadBoardTotals{
+		{StageID: nil, Count: 1, ExpectedRevenue: 50},
+		{StageID: &wonID, Count: 1, ExpectedRevenue: 1000},
+		{StageID: &newID, Count: 3, ExpectedRevenue: 300},
+	}
+	cards := []*types.Lead{
+		{Name: "a", StageID: &newID},
+		{Name: "w", StageID: &wonID},
+		{Name: "u"},
+		{Name: "b", StageID: &newID},
+	}
+
+	board := buildLeadBoard(types.LeadFilter{TeamID: &teamID}, stages, totals, cards, map[uuid.UUID]int{})
+	require.Len(t, board.Columns, 3, "the other team's empty stage is left out")
+	assert.Equal(t, 5, board.Total)
+	assert.Equal(t, 1350.0, board.ExpectedRevenue)
+
+	assert.Equal(t, "New", board.Columns[0].StageName)
+	assert.Equal(t, 3, board.Columns[0].Count)
+	require.Len(t, board.Columns[0].Leads, 2)
+	assert.Equal(t, encodeLeadBoardCursor(newID, 2), board.Columns[0].NextCursor)
+
+	assert.Equal(t, "Won", board.Columns[1].StageName)
+	assert.True(t, board.Columns[1].IsWon)
+	assert.Empty(t, board.Columns[1].NextCursor, "every card is loaded")
+
+	assert.Nil(t, board.Columns[2].StageID, "leads without a stage come last")
+	assert.Equal(t, "u", board.Columns[2].Leads[0].Name)
+}
+
+func TestBuildLeadBoardContinuesFromCursor(t *testing.T) {
+	stageID := uuid.New()
+	stages := []*types.LeadStage{{ID: stageID, Name: "New"}, {ID: uuid.New(), Name: "Qualified"}}
+	totals := []types.LeadBoardTotals{{StageID: &stageID, Count: 5}}
+	cards := []*types.Lead{{StageID: &stageID}, {StageID: &stageID}}
+
+	board := buildLeadBoard(types.LeadFilter{StageID: &stageID}, stages, totals, cards, map[uuid.UUID]int{stageID: 2})
+	require.Len(t, board.Columns, 1)
+	assert.Equal(t, encodeLeadBoardCursor(stageID, 4), board.Columns[0].NextCursor)
+}
+
+func TestLeadBoardCursorRoundTrip(t *testing.T) {
+	stageID := uuid.New()
+	for _, key := range []uuid.UUID{stageID, uuid.Nil} {
+		decoded, offset, err := decodeLeadBoardCursor(encodeLeadBoardCursor(key, 40))
+		require.NoError(t, err)
+		assert.Equal(t, key, decoded)
+		assert.Equal(t, 40, offset)
+	}
+
+	for _, cursor := range []string{"", "not base64!", encodeLeadBoardCursor(stageID, -1)} {
+		_, _, err := decodeLeadBoardCursor(cursor)
+		assert.True(t, errors.Is(err, types.ErrInvalidLeadBoard), cursor)
+	}
+}
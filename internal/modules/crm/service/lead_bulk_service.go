@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// SetBulk enables bulk lead updates and deletes
+func (s *LeadService) SetBulk(bulk types.LeadBulkRepository) {
+	s.bulk = bulk
+}
+
+// BulkUpdateLeads applies the same changes to many leads in one transaction.
+// A stage change is checked against each lead's pipeline like MoveStage and
+// recorded in its stage history. Leads that cannot be changed are reported
+// as failed and, unless the request is all-or-nothing, the others are still
+// changed.
+func (s *LeadService) BulkUpdateLeads(ctx context.Context, orgID uuid.UUID, req types.LeadBulkUpdateRequest) (*types.LeadBulkResult, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.bulk == nil {
+		return nil, errors.New("bulk lead updates are not available")
+	}
+
+	changes := req.Changes
+	if changes.IsEmpty() {
+		return nil, fmt.Errorf("%w: changes are required", types.ErrInvalidLeadBulkRequest)
+	}
+	if changes.Priority != nil && !changes.Priority.IsValid() {
+		return nil, fmt.Errorf("%w: unknown priority %q", types.ErrInvalidLeadBulkRequest, *changes.Priority)
+	}
+	for _, id := range changes.AddTagIDs {
+		for _, removed := range changes.RemoveTagIDs {
+			if id == removed {
+				return nil, fmt.Errorf("%w: tag %s is both added and removed", types.ErrInvalidLeadBulkRequest, id)
+			}
+		}
+	}
+
+	var stage *types.LeadStage
+	if changes.StageID != nil {
+		if s.stageHistory == nil || s.stageRepo == nil {
+			return nil, errors.New("lead stage history is not available")
+		}
+		found, err := s.stageRepo.FindByID(ctx, *changes.StageID)
+		if err != nil {
+			return nil, err
+		}
+		if found.OrganizationID != orgID {
+			return nil, errors.New("lead stage not found or access denied")
+		}
+		stage = found
+	}
+
+	targets, requested, err := s.findBulkTargets(ctx, orgID, req.IDs, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := types.LeadBulkBatch{
+		OrganizationID: orgID,
+		Changes:        changes,
+		AllOrNothing:   req.AllOrNothing,
+		ChangedAt:      time.Now(),
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		batch.ChangedBy = &userID
+	}
+
+	// Failures found before the transaction, keyed by lead
+	failures := make(map[uuid.UUID]string)
+	if stage != nil {
+		batch.Probability = stage.Probability
+		batch.FromStageIDs = make(map[uuid.UUID]*uuid.UUID, len(targets))
+		transitions := make(map[uuid.UUID]*types.LeadStageTransitions)
+		for _, target := range targets {
+			reason, err := s.checkBulkMove(ctx, orgID, target, stage, transitions)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				failures[target.ID] = reason
+				continue
+			}
+			batch.FromStageIDs[target.ID] = target.StageID
+		}
+	}
+
+	found := make(map[uuid.UUID]bool, len(targets))
+	for _, target := range targets {
+		found[target.ID] = true
+		if _, failed := failures[target.ID]; !failed {
+			batch.LeadIDs = append(batch.LeadIDs, target.ID)
+		}
+	}
+	for _, id := range requested {
+		if !found[id] {
+			failures[id] = "lead not found"
+		}
+	}
+
+	var applied []types.LeadBulkItemResult
+	if len(batch.LeadIDs) > 0 && !(req.AllOrNothing && len(failures) > 0) {
+		applied, err = s.bulk.Update(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order := requested
+	if order == nil {
+		order = make([]uuid.UUID, len(targets))
+		for i, target := range targets {
+			order[i] = target.ID
+		}
+	}
+	result := collectBulkResults(order, applied, failures, types.LeadBulkStatusUpdated, req.AllOrNothing)
+
+	if s.eventBus != nil && result.Succeeded > 0 {
+		s.eventBus.Publish(ctx, "crm.lead.bulk_updated", map[string]interface{}{
+			"organization_id": orgID,
+			"lead_ids":        succeededLeadIDs(result),
+			"changes":         changes,
+		})
+	}
+
+	return result, nil
+}
+
+// BulkDeleteLeads deletes many leads with one statement
+func (s *LeadService) BulkDeleteLeads(ctx context.Context, orgID uuid.UUID, req types.LeadBulkDeleteRequest) (*types.LeadBulkResult, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:delete"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.bulk == nil {
+		return nil, errors.New("bulk lead updates are not available")
+	}
+
+	targets, requested, err := s.findBulkTargets(ctx, orgID, req.IDs, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(targets))
+	for i, target := range targets {
+		ids[i] = target.ID
+	}
+	order := requested
+	if order == nil {
+		order = ids
+	}
+
+	var deletedBy *uuid.UUID
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		deletedBy = &userID
+	}
+
+	var applied []types.LeadBulkItemResult
+	if len(ids) > 0 {
+		deleted, err := s.bulk.Delete(ctx, orgID, ids, deletedBy, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		applied = make([]types.LeadBulkItemResult, len(deleted))
+		for i, id := range deleted {
+			applied[i] = types.LeadBulkItemResult{LeadID: id, Status: types.LeadBulkStatusDeleted}
+		}
+	}
+
+	failures := make(map[uuid.UUID]string)
+	deleted := make(map[uuid.UUID]bool, len(applied))
+	for _, item := range applied {
+		deleted[item.LeadID] = true
+	}
+	for _, id := range order {
+		if !deleted[id] {
+			failures[id] = "lead not found"
+		}
+	}
+	result := collectBulkResults(order, applied, failures, types.LeadBulkStatusDeleted, false)
+
+	if s.eventBus != nil && result.Succeeded > 0 {
+		s.eventBus.Publish(ctx, "crm.lead.bulk_deleted", map[string]interface{}{
+			"organization_id": orgID,
+			"lead_ids":        succeededLeadIDs(result),
+		})
+	}
+
+	return result, nil
+}
+
+// findBulkTargets resolves the leads of a bulk request. It returns the
+// requested IDs, deduplicated, or nil when the request uses a filter.
+func (s *LeadService) findBulkTargets(ctx context.Context, orgID uuid.UUID, ids []uuid.UUID, filter *types.LeadBulkFilter) ([]types.LeadBulkTarget, []uuid.UUID, error) {
+	switch {
+	case len(ids) > 0 && filter != nil:
+		return nil, nil, fmt.Errorf("%w: give either ids or a filter, not both", types.ErrInvalidLeadBulkRequest)
+	case len(ids) == 0 && filter == nil:
+		return nil, nil, fmt.Errorf("%w: ids or a filter is required", types.ErrInvalidLeadBulkRequest)
+	case filter != nil && filter.IsEmpty():
+		return nil, nil, fmt.Errorf("%w: filter must set at least one field", types.ErrInvalidLeadBulkRequest)
+	case filter != nil && filter.Priority != nil && !filter.Priority.IsValid():
+		return nil, nil, fmt.Errorf("%w: unknown priority %q", types.ErrInvalidLeadBulkRequest, *filter.Priority)
+	case filter != nil && filter.Status != nil && !filter.Status.IsValid():
+		return nil, nil, fmt.Errorf("%w: unknown status %q", types.ErrInvalidLeadBulkRequest, *filter.Status)
+	}
+
+	if filter != nil {
+		// One more than allowed tells a filter matching too many leads apart
+		targets, err := s.bulk.FindTargets(ctx, filter.LeadFilter(orgID), nil, types.MaxLeadBulkSize+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(targets) > types.MaxLeadBulkSize {
+			return nil, nil, fmt.Errorf("%w: filter matches more than %d leads", types.ErrInvalidLeadBulkRequest, types.MaxLeadBulkSize)
+		}
+		return targets, nil, nil
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	requested := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			requested = append(requested, id)
+		}
+	}
+	if len(requested) > types.MaxLeadBulkSize {
+		return nil, nil, fmt.Errorf("%w: at most %d leads per request", types.ErrInvalidLeadBulkRequest, types.MaxLeadBulkSize)
+	}
+
+	targets, err := s.bulk.FindTargets(ctx, types.LeadFilter{OrganizationID: orgID}, requested, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return targets, requested, nil
+}
+
+// checkBulkMove returns why the lead cannot move to the stage, or "" when
+// its pipeline allows the move. Transitions are cached by team.
+func (s *LeadService) checkBulkMove(ctx context.Context, orgID uuid.UUID, target types.LeadBulkTarget, stage *types.LeadStage, cache map[uuid.UUID]*types.LeadStageTransitions) (string, error) {
+	if target.StageID != nil && *target.StageID == stage.ID {
+		return fmt.Sprintf("%s: lead is already in stage %s", types.ErrStageTransitionNotAllowed, stage.Name), nil
+	}
+	if stage.TeamID != nil && (target.TeamID == nil || *target.TeamID != *stage.TeamID) {
+		return fmt.Sprintf("%s: stage %s belongs to another pipeline", types.ErrStageTransitionNotAllowed, stage.Name), nil
+	}
+
+	var team uuid.UUID
+	if target.TeamID != nil {
+		team = *target.TeamID
+	}
+	transitions, ok := cache[team]
+	if !ok {
+		found, err := s.stageHistory.FindTransitions(ctx, orgID, target.TeamID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get lead stage transitions: %w", err)
+		}
+		transitions = found
+		cache[team] = found
+	}
+	if !transitions.Allows(target.StageID, stage.ID) {
+		return fmt.Sprintf("%s: lead cannot move to stage %s from its current stage", types.ErrStageTransitionNotAllowed, stage.Name), nil
+	}
+	return "", nil
+}
+
+// collectBulkResults merges the repository's results with the failures found
+// before it ran, in the given order. When an all-or-nothing request had a
+// failure, the leads that would have succeeded are reported as skipped.
+func collectBulkResults(order []uuid.UUID, applied []types.LeadBulkItemResult, failures map[uuid.UUID]string, success types.LeadBulkStatus, allOrNothing bool) *types.LeadBulkResult {
+	byLead := make(map[uuid.UUID]types.LeadBulkItemResult, len(applied))
+	for _, item := range applied {
+		byLead[item.LeadID] = item
+	}
+
+	result := &types.LeadBulkResult{Matched: len(order), Results: make([]types.LeadBulkItemResult, 0, len(order))}
+	for _, id := range order {
+		item, ok := byLead[id]
+		if reason, failed := failures[id]; failed {
+			item = types.LeadBulkItemResult{LeadID: id, Status: types.LeadBulkStatusFailed, Error: reason}
+		} else if !ok {
+			item = types.LeadBulkItemResult{LeadID: id, Status: types.LeadBulkStatusSkipped}
+		}
+		switch item.Status {
+		case types.LeadBulkStatusFailed:
+			result.Failed++
+		case success:
+			result.Succeeded++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	if allOrNothing && result.Failed > 0 {
+		for i := range result.Results {
+			if result.Results[i].Status == success {
+				result.Results[i].Status = types.LeadBulkStatusSkipped
+			}
+		}
+		result.Succeeded = 0
+	}
+	return result
+}
+
+func succeededLeadIDs(result *types.LeadBulkResult) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, result.Succeeded)
+	for _, item := range result.Results {
+		if item.Status == types.LeadBulkStatusUpdated || item.Status == types.LeadBulkStatusDeleted {
+			ids = append(ids, item.LeadID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LeadCadenceInterval is how often leads are enrolled in cadences and
+	// enrollments are completed or stopped
+	LeadCadenceInterval = time.Minute
+	// leadCadenceBatchSize bounds the leads enrolled per pass
+	leadCadenceBatchSize = 500
+)
+
+// SetCadences enables follow-up cadences. The sources repository checks
+// that a cadence's source belongs to the organization.
+func (s *LeadService) SetCadences(cadences types.LeadCadenceRepository, sources types.LeadSourceRepository) {
+	s.cadences = cadences
+	s.sources = sources
+}
+
+// ListCadences lists the organization's cadences, most specific first
+func (s *LeadService) ListCadences(ctx context.Context, orgID uuid.UUID) ([]types.LeadCadence, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_cadences:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.ListCadences(ctx, orgID)
+}
+
+// GetCadence retrieves a cadence with its steps
+func (s *LeadService) GetCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.LeadCadence, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_cadences:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.FindCadence(ctx, orgID, id)
+}
+
+// CreateCadence adds a follow-up cadence for leads of a source and/or stage
+func (s *LeadService) CreateCadence(ctx context.Context, orgID uuid.UUID, req types.LeadCadenceRequest) (*types.LeadCadence, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_cadences:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	now := time.Now()
+	cadence := types.LeadCadence{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.applyCadenceRequest(ctx, &cadence, req); err != nil {
+		return nil, err
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		cadence.CreatedBy = &userID
+		cadence.UpdatedBy = &userID
+	}
+
+	return s.cadences.CreateCadence(ctx, cadence)
+}
+
+// UpdateCadence replaces a cadence and its steps. Leads already enrolled
+// keep the tasks they were given; deactivating a cadence only stops new
+// enrollments.
+func (s *LeadService) UpdateCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadCadenceRequest) (*types.LeadCadence, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_cadences:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	cadence, err := s.cadences.FindCadence(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCadenceRequest(ctx, cadence, req); err != nil {
+		return nil, err
+	}
+	cadence.UpdatedAt = time.Now()
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		cadence.UpdatedBy = &userID
+	}
+
+	return s.cadences.UpdateCadence(ctx, *cadence)
+}
+
+// DeleteCadence removes a cadence, stopping its active enrollments and
+// cancelling their planned activities
+func (s *LeadService) DeleteCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_cadences:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.DeleteCadence(ctx, orgID, id, time.Now())
+}
+
+func (s *LeadService) applyCadenceRequest(ctx context.Context, cadence *types.LeadCadence, req types.LeadCadenceRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrInvalidLeadCadence)
+	}
+	if len(req.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", types.ErrInvalidLeadCadence)
+	}
+
+	steps := make([]types.LeadCadenceStep, len(req.Steps))
+	for i, step := range req.Steps {
+		switch step.ActivityType {
+		case types.ActivityTypeCall, types.ActivityTypeMeeting, types.ActivityTypeEmail, types.ActivityTypeTodo:
+		default:
+			return fmt.Errorf("%w: step %d has unknown activity type %q", types.ErrInvalidLeadCadence, i+1, step.ActivityType)
+		}
+		step.Summary = strings.TrimSpace(step.Summary)
+		if step.Summary == "" {
+			return fmt.Errorf("%w: step %d needs a summary", types.ErrInvalidLeadCadence, i+1)
+		}
+		if step.OffsetMinutes < 0 {
+			return fmt.Errorf("%w: step %d has a negative offset", types.ErrInvalidLeadCadence, i+1)
+		}
+		if i > 0 && step.OffsetMinutes < steps[i-1].OffsetMinutes {
+			return fmt.Errorf("%w: step %d is due before step %d", types.ErrInvalidLeadCadence, i+1, i)
+		}
+		step.Sequence = i + 1
+		steps[i] = step
+	}
+
+	if req.SourceID != nil && s.sources != nil {
+		source, err := s.sources.FindByID(ctx, *req.SourceID)
+		if err != nil {
+			return err
+		}
+		if source.OrganizationID != cadence.OrganizationID {
+			return errors.New("lead source not found or access denied")
+		}
+	}
+	if req.StageID != nil && s.stageRepo != nil {
+		stage, err := s.stageRepo.FindByID(ctx, *req.StageID)
+		if err != nil {
+			return err
+		}
+		if stage.OrganizationID != cadence.OrganizationID {
+			return errors.New("lead stage not found or access denied")
+		}
+	}
+
+	cadence.Name = name
+	cadence.SourceID = req.SourceID
+	cadence.StageID = req.StageID
+	cadence.Steps = steps
+	if req.Active != nil {
+		cadence.Active = *req.Active
+	}
+	return nil
+}
+
+// GetLeadCadences lists the lead's cadence enrollments with their tasks
+func (s *LeadService) GetLeadCadences(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]types.LeadCadenceEnrollment, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.FindEnrollments(ctx, orgID, leadID)
+}
+
+// GetCadencePerformance reports, per cadence, how many leads completed it
+// or dropped out and how many tasks were done or skipped
+func (s *LeadService) GetCadencePerformance(ctx context.Context, orgID uuid.UUID) ([]types.LeadCadencePerformance, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.Performance(ctx, orgID)
+}
+
+// SkipCadenceTask skips a pending cadence task, cancelling its activity
+func (s *LeadService) SkipCadenceTask(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.LeadCadenceTask, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	var skippedBy *uuid.UUID
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		skippedBy = &userID
+	}
+	return s.cadences.SkipTask(ctx, orgID, id, skippedBy, time.Now())
+}
+
+// StopCadenceEnrollment takes a lead out of a cadence, skipping its
+// pending tasks
+func (s *LeadService) StopCadenceEnrollment(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	if s.cadences == nil {
+		return errors.New("lead cadences are not available")
+	}
+
+	return s.cadences.StopEnrollment(ctx, orgID, id, time.Now())
+}
+
+// RunCadences enrolls the leads entering the scope of a cadence, creating
+// an activity due at each step's offset from the entry, and completes or
+// stops enrollments as their tasks are closed and their leads move on. It
+// is invoked by the scheduled job and therefore bypasses the per-request
+// permission check. Failures of single leads do not stop the pass and are
+// returned together.
+func (s *LeadService) RunCadences(ctx context.Context, now time.Time) (*types.LeadCadenceRun, error) {
+	if s.cadences == nil {
+		return nil, errors.New("lead cadences are not available")
+	}
+
+	var run types.LeadCadenceRun
+	var errs []error
+
+	entries, err := s.cadences.UnenrolledEntries(ctx, leadCadenceBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	cadences := make(map[uuid.UUID]*types.LeadCadence)
+	for _, entry := range entries {
+		cadence, ok := cadences[entry.CadenceID]
+		if !ok {
+			if cadence, err = s.cadences.FindCadence(ctx, entry.OrganizationID, entry.CadenceID); err != nil {
+				errs = append(errs, fmt.Errorf("lead %s: %w", entry.LeadID, err))
+				continue
+			}
+			cadences[entry.CadenceID] = cadence
+		}
+
+		enrolled, err := s.cadences.Enroll(ctx, newCadenceEnrollment(entry, cadence))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lead %s: %w", entry.LeadID, err))
+			continue
+		}
+		if enrolled {
+			run.Enrolled++
+		}
+	}
+
+	if run.Completed, run.Stopped, err = s.cadences.SyncEnrollments(ctx, now); err != nil {
+		return nil, err
+	}
+
+	return &run, errors.Join(errs...)
+}
+
+// newCadenceEnrollment lays the cadence's steps out from the lead's entry
+func newCadenceEnrollment(entry types.LeadCadenceEntry, cadence *types.LeadCadence) types.LeadCadenceEnrollment {
+	cadenceID := cadence.ID
+	enrollment := types.LeadCadenceEnrollment{
+		ID:             uuid.New(),
+		OrganizationID: entry.OrganizationID,
+		LeadID:         entry.LeadID,
+		CadenceID:      &cadenceID,
+		CadenceName:    cadence.Name,
+		SourceID:       cadence.SourceID,
+		StageID:        cadence.StageID,
+		AssignedTo:     entry.AssignedTo,
+		EnteredAt:      entry.EnteredAt,
+		Status:         types.LeadCadenceEnrollmentActive,
+		Tasks:          make([]types.LeadCadenceTask, 0, len(cadence.Steps)),
+	}
+	for _, step := range cadence.Steps {
+		enrollment.Tasks = append(enrollment.Tasks, types.LeadCadenceTask{
+			ID:           uuid.New(),
+			EnrollmentID: enrollment.ID,
+			Sequence:     step.Sequence,
+			ActivityType: step.ActivityType,
+			Summary:      step.Summary,
+			Note:         step.Note,
+			DueAt:        entry.EnteredAt.Add(time.Duration(step.OffsetMinutes) * time.Minute),
+			Status:       types.LeadCadenceTaskPending,
+		})
+	}
+	return enrollment
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+// LeadCaptureService turns web form submissions into leads, deduplicating
+// resubmissions according to each capture form's configuration
+type LeadCaptureService struct {
+	formRepo    types.LeadCaptureFormRepository
+	leadRepo    types.LeadRepository
+	leadService *LeadService
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewLeadCaptureService(formRepo types.LeadCaptureFormRepository, leadRepo types.LeadRepository, leadService *LeadService, authService auth.LegacyAuthService, eventBus *events.Bus) *LeadCaptureService {
+	return &LeadCaptureService{
+		formRepo:    formRepo,
+		leadRepo:    leadRepo,
+		leadService: leadService,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "lead-capture"),
+		now:         time.Now,
+	}
+}
+
+func (s *LeadCaptureService) CreateForm(ctx context.Context, orgID uuid.UUID, req types.LeadCaptureFormCreateRequest) (*types.LeadCaptureForm, error) {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	now := s.now()
+	form := types.LeadCaptureForm{
+		ID:                 uuid.New(),
+		OrganizationID:     orgID,
+		Name:               strings.TrimSpace(req.Name),
+		SourceID:           req.SourceID,
+		TeamID:             req.TeamID,
+		DedupWindowMinutes: types.DefaultDedupWindowMinutes,
+		MergeStrategy:      types.LeadMergeStrategyUpdateExisting,
+		Active:             true,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if req.DedupWindowMinutes != nil {
+		form.DedupWindowMinutes = *req.DedupWindowMinutes
+	}
+	if req.MergeStrategy != "" {
+		form.MergeStrategy = req.MergeStrategy
+	}
+	if req.Active != nil {
+		form.Active = *req.Active
+	}
+
+	if err := validateLeadCaptureForm(form); err != nil {
+		return nil, fmt.Errorf("invalid lead capture form: %w", err)
+	}
+
+	created, err := s.formRepo.Create(ctx, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lead capture form: %w", err)
+	}
+
+	s.logger.Info("Created lead capture form", "form_id", created.ID, "merge_strategy", created.MergeStrategy)
+
+	return created, nil
+}
+
+func (s *LeadCaptureService) GetForm(ctx context.Context, orgID, id uuid.UUID) (*types.LeadCaptureForm, error) {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.getForm(ctx, orgID, id)
+}
+
+func (s *LeadCaptureService) ListForms(ctx context.Context, orgID uuid.UUID, filter types.LeadCaptureFormFilter) ([]*types.LeadCaptureForm, error) {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	filter.OrganizationID = orgID
+	forms, err := s.formRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lead capture forms: %w", err)
+	}
+
+	return forms, nil
+}
+
+func (s *LeadCaptureService) UpdateForm(ctx context.Context, orgID, id uuid.UUID, req types.LeadCaptureFormUpdateRequest) (*types.LeadCaptureForm, error) {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	form, err := s.getForm(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		form.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.SourceID != nil {
+		form.SourceID = req.SourceID
+	}
+	if req.TeamID != nil {
+		form.TeamID = req.TeamID
+	}
+	if req.DedupWindowMinutes != nil {
+		form.DedupWindowMinutes = *req.DedupWindowMinutes
+	}
+	if req.MergeStrategy != nil {
+		form.MergeStrategy = *req.MergeStrategy
+	}
+	if req.Active != nil {
+		form.Active = *req.Active
+	}
+	form.UpdatedAt = s.now()
+
+	if err := validateLeadCaptureForm(*form); err != nil {
+		return nil, fmt.Errorf("invalid lead capture form: %w", err)
+	}
+
+	updated, err := s.formRepo.Update(ctx, *form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lead capture form: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (s *LeadCaptureService) DeleteForm(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+
+	if _, err := s.getForm(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	if err := s.formRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete lead capture form: %w", err)
+	}
+
+	return nil
+}
+
+// Submit captures a lead through a form. When the submitted email matches an
+// open lead created inside the form's dedup window, the form's merge strategy
+// decides whether that lead is updated, returned unchanged, or ignored in
+// favour of a new lead.
+func (s *LeadCaptureService) Submit(ctx context.Context, orgID, formID uuid.UUID, req types.LeadCreateRequest) (*types.LeadCaptureResult, error) {
+	if err := s.authService.CheckPermission(ctx, "lead_capture_forms:submit"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	form, err := s.getForm(ctx, orgID, formID)
+	if err != nil {
+		return nil, err
+	}
+	if !form.Active {
+		return nil, errors.New("lead capture form is not active")
+	}
+
+	result := &types.LeadCaptureResult{
+		FormID:        form.ID,
+		MergeStrategy: form.MergeStrategy,
+	}
+
+	email := ""
+	if req.Email != nil {
+		email = strings.TrimSpace(*req.Email)
+	}
+
+	if email != "" && form.MergeStrategy != types.LeadMergeStrategyCreateNew && form.DedupWindowMinutes > 0 {
+		since := s.now().Add(-form.DedupWindow())
+		existing, err := s.leadRepo.FindOpenByEmailSince(ctx, orgID, email, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for duplicate lead: %w", err)
+		}
+
+		if existing != nil {
+			result.MatchedLeadID = &existing.ID
+
+			if form.MergeStrategy == types.LeadMergeStrategyKeepExisting {
+				result.Lead = *existing
+				result.Outcome = types.LeadCaptureOutcomeDuplicate
+				return result, nil
+			}
+
+			update, fields := captureMergeUpdate(*existing, req)
+			result.MergedFields = fields
+			result.Outcome = types.LeadCaptureOutcomeMerged
+			if len(fields) == 0 {
+				result.Lead = *existing
+				return result, nil
+			}
+
+			merged, err := s.leadService.UpdateLead(ctx, orgID, existing.ID, update)
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge lead: %w", err)
+			}
+			result.Lead = merged
+
+			s.logger.Info("Merged lead capture submission", "form_id", form.ID, "lead_id", merged.ID, "fields", fields)
+			return result, nil
+		}
+	}
+
+	// Form defaults only fill what the submission left empty
+	if req.SourceID == nil {
+		req.SourceID = form.SourceID
+	}
+	if req.TeamID == nil {
+		req.TeamID = form.TeamID
+	}
+	req.Active = true
+
+	created, err := s.leadService.CreateLead(ctx, orgID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Lead = created
+	result.Outcome = types.LeadCaptureOutcomeCreated
+	return result, nil
+}
+
+func (s *LeadCaptureService) getForm(ctx context.Context, orgID, id uuid.UUID) (*types.LeadCaptureForm, error) {
+	form, err := s.formRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lead capture form: %w", err)
+	}
+	if form.OrganizationID != orgID {
+		return nil, fmt.Errorf("lead capture form does not belong to organization: %w", errors.New("access denied"))
+	}
+	return form, nil
+}
+
+func validateLeadCaptureForm(form types.LeadCaptureForm) error {
+	if form.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(form.Name) > 255 {
+		return errors.New("name must be 255 characters or less")
+	}
+	if !form.MergeStrategy.IsValid() {
+		return fmt.Errorf("invalid merge strategy: %s", form.MergeStrategy)
+	}
+	if form.DedupWindowMinutes < 0 || form.DedupWindowMinutes > types.MaxDedupWindowMinutes {
+		return fmt.Errorf("dedup window must be between 0 and %d minutes", types.MaxDedupWindowMinutes)
+	}
+	return nil
+}
+
+// captureMergeUpdate builds the update applied to an open lead by a resubmission.
+// Only submitted values that differ from the lead are applied; attribution
+// (source, team, assignment) and pipeline state are left untouched.
+func captureMergeUpdate(existing types.Lead, req types.LeadCreateRequest) (types.LeadUpdateRequest, []string) {
+	var update types.LeadUpdateRequest
+	var fields []string
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != existing.Name {
+		update.Name = &name
+		fields = append(fields, "name")
+	}
+
+	mergeString := func(field string, submitted, current *string, target **string) {
+		if submitted == nil || strings.TrimSpace(*submitted) == "" {
+			return
+		}
+		if current != nil && *current == *submitted {
+			return
+		}
+		*target = submitted
+		fields = append(fields, field)
+	}
+	mergeString("contact_name", req.ContactName, existing.ContactName, &update.ContactName)
+	mergeString("phone", req.Phone, existing.Phone, &update.Phone)
+	mergeString("mobile", req.Mobile, existing.Mobile, &update.Mobile)
+	mergeString("website", req.Website, existing.Website, &update.Website)
+	mergeString("description", req.Description, existing.Description, &update.Description)
+	mergeString("street", req.Street, existing.Street, &update.Street)
+	mergeString("street2", req.Street2, existing.Street2, &update.Street2)
+	mergeString("city", req.City, existing.City, &update.City)
+	mergeString("zip", req.Zip, existing.Zip, &update.Zip)
+
+	mergeUUID := func(field string, submitted, current *uuid.UUID, target **uuid.UUID) {
+		if submitted == nil || (current != nil && *current == *submitted) {
+			return
+		}
+		*target = submitted
+		fields = append(fields, field)
+	}
+	mergeUUID("state_id", req.StateID, existing.StateID, &update.StateID)
+	mergeUUID("country_id", req.CountryID, existing.CountryID, &update.CountryID)
+
+	if req.ExpectedRevenue != nil && (existing.ExpectedRevenue == nil || *existing.ExpectedRevenue != *req.ExpectedRevenue) {
+		update.ExpectedRevenue = req.ExpectedRevenue
+		fields = append(fields, "expected_revenue")
+	}
+	if req.CustomFields != nil && !reflect.DeepEqual(req.CustomFields, existing.CustomFields) {
+		update.CustomFields = req.CustomFields
+		fields = append(fields, "custom_fields")
+	}
+
+	return update, fields
+}
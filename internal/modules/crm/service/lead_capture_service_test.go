@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+// allowAuth grants every permission in one organization
+type allowAuth struct{ orgID uuid.UUID }
+
+func (a allowAuth) CheckPermission(context.Context, string) error { return nil }
+
+func (a allowAuth) GetOrganizationID(context.Context) (uuid.UUID, error) { return a.orgID, nil }
+
+func (a allowAuth) GetUserID(context.Context) (uuid.UUID, error) { return uuid.Nil, nil }
+
+// leadStore keeps the open lead a lookup finds, every lead written and every
+// list filter; the methods a test does not set panic through the nil
+// embedded interface
+type leadStore struct {
+	types.LeadRepository
+	existing *types.Lead
+	since    time.Time
+	written  []types.Lead
+	filters  []types.LeadFilter
+}
+
+func (s *leadStore) FindOpenByEmailSince(_ context.Context, _ uuid.UUID, _ string, since time.Time) (*types.Lead, error) {
+	s.since = since
+	return s.existing, nil
+}
+
+func (s *leadStore) FindByID(context.Context, uuid.UUID) (*types.Lead, error) {
+	lead := *s.existing
+	return &lead, nil
+}
+
+func (s *leadStore) Create(_ context.Context, lead types.Lead) (*types.Lead, error) {
+	s.written = append(s.written, lead)
+	return &lead, nil
+}
+
+func (s *leadStore) Update(_ context.Context, lead types.Lead) (*types.Lead, error) {
+	s.written = append(s.written, lead)
+	return &lead, nil
+}
+
+// captureForms serves one form
+type captureForms struct {
+	types.LeadCaptureFormRepository
+	form types.LeadCaptureForm
+}
+
+func (f *captureForms) FindByID(context.Context, uuid.UUID) (*types.LeadCaptureForm, error) {
+	form := f.form
+	return &form, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func newTestLeadCapture(strategy types.LeadMergeStrategy, existing *types.Lead) (*LeadCaptureService, *leadStore, uuid.UUID) {
+	orgID := uuid.New()
+	sourceID := uuid.New()
+	forms := &captureForms{form: types.LeadCaptureForm{
+		ID:                 uuid.New(),
+		OrganizationID:     orgID,
+		Name:               "Contact us",
+		SourceID:           &sourceID,
+		DedupWindowMinutes: types.DefaultDedupWindowMinutes,
+		MergeStrategy:      strategy,
+		Active:             true,
+	}}
+	if existing != nil {
+		existing.OrganizationID = orgID
+	}
+
+	leads := &leadStore{existing: existing}
+	auth := allowAuth{orgID: orgID}
+	svc := NewLeadCaptureService(forms, leads, NewLeadService(leads, auth, nil, nil), auth, nil)
+	return svc, leads, orgID
+}
+
+func TestLeadCaptureCreatesLeadWithoutMatch(t *testing.T) {
+	svc, leads, orgID := newTestLeadCapture(types.LeadMergeStrategyUpdateExisting, nil)
+
+	result, err := svc.Submit(context.Background(), orgID, uuid.Nil, types.LeadCreateRequest{
+		Name:  "Website enquiry",
+		Email: strPtr("jane@example.com"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.LeadCaptureOutcomeCreated, result.Outcome)
+	assert.Nil(t, result.MatchedLeadID)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), leads.since, time.Minute, "the form's dedup window bounds the lookup")
+	require.Len(t, leads.written, 1)
+	assert.NotNil(t, leads.written[0].SourceID, "form source should be applied")
+	assert.True(t, leads.written[0].Active)
+}
+
+func TestLeadCaptureUpdatesOpenLeadInWindow(t *testing.T) {
+	existing := &types.Lead{
+		ID:    uuid.New(),
+		Name:  "Website enquiry",
+		Email: strPtr("Jane@Example.com"),
+		Phone: strPtr("555-0100"),
+	}
+	svc, leads, orgID := newTestLeadCapture(types.LeadMergeStrategyUpdateExisting, existing)
+
+	result, err := svc.Submit(context.Background(), orgID, uuid.Nil, types.LeadCreateRequest{
+		Name:  "Website enquiry",
+		Email: strPtr("jane@example.com"),
+		Phone: strPtr("555-0100"),
+		City:  strPtr("Lisbon"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.LeadCaptureOutcomeMerged, result.Outcome)
+	assert.Equal(t, existing.ID, *result.MatchedLeadID)
+	assert.Equal(t, []string{"city"}, result.MergedFields)
+	require.Len(t, leads.written, 1)
+	assert.Equal(t, "Lisbon", *leads.written[0].City)
+}
+
+func TestLeadCaptureKeepExistingReturnsDuplicate(t *testing.T) {
+	existing := &types.Lead{ID: uuid.New(), Name: "Website enquiry"}
+	svc, leads, orgID := newTestLeadCapture(types.LeadMergeStrategyKeepExisting, existing)
+
+	result, err := svc.Submit(context.Background(), orgID, uuid.Nil, types.LeadCreateRequest{
+		Name:  "Website enquiry",
+		Email: strPtr("jane@example.com"),
+		City:  strPtr("Lisbon"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.LeadCaptureOutcomeDuplicate, result.Outcome)
+	assert.Equal(t, existing.ID, result.Lead.ID)
+	assert.Empty(t, leads.written)
+}
+
+func TestLeadCaptureCreateNewSkipsLookup(t *testing.T) {
+	existing := &types.Lead{ID: uuid.New(), Name: "Website enquiry"}
+	svc, leads, orgID := newTestLeadCapture(types.LeadMergeStrategyCreateNew, existing)
+
+	result, err := svc.Submit(context.Background(), orgID, uuid.Nil, types.LeadCreateRequest{
+		Name:  "Website enquiry",
+		Email: strPtr("jane@example.com"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.LeadCaptureOutcomeCreated, result.Outcome)
+	assert.Len(t, leads.written, 1)
+}
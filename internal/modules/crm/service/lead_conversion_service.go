@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// SetConversion enables converting leads to opportunities
+func (s *LeadService) SetConversion(conversion types.LeadConversionRepository) {
+	s.conversion = conversion
+}
+
+// ConvertLead converts a lead to an opportunity linked to a new or existing
+// contact, optionally raising a draft quotation, all in one transaction.
+// The lead keeps its ID, so its activities and attachments stay with it.
+func (s *LeadService) ConvertLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadConvertRequest) (*types.LeadConversion, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if req.ContactID == nil {
+		// The contact is only created when the lead has none to link to,
+		// which is decided under the lead's lock; check the permission up front
+		if err := s.authService.CheckPermission(ctx, "crm:contacts:create"); err != nil {
+			return nil, fmt.Errorf("permission denied: %w", err)
+		}
+	}
+	if req.Quotation != nil {
+		if err := s.authService.CheckPermission(ctx, "sales:sales_orders:create"); err != nil {
+			return nil, fmt.Errorf("permission denied: %w", err)
+		}
+	}
+	if s.conversion == nil {
+		return nil, errors.New("lead conversion is not available")
+	}
+
+	if err := validateLeadConvertRequest(&req); err != nil {
+		return nil, err
+	}
+
+	var userID *uuid.UUID
+	if id, err := s.authService.GetUserID(ctx); err == nil {
+		userID = &id
+	}
+
+	conversion, err := s.conversion.Convert(ctx, orgID, id, userID, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.converted", conversion)
+	}
+
+	return conversion, nil
+}
+
+func validateLeadConvertRequest(req *types.LeadConvertRequest) error {
+	if req.OpportunityName != nil {
+		name := strings.TrimSpace(*req.OpportunityName)
+		if name == "" {
+			return fmt.Errorf("%w: opportunity name cannot be empty", types.ErrInvalidLeadConversion)
+		}
+		req.OpportunityName = &name
+	}
+	if req.ExpectedRevenue != nil && *req.ExpectedRevenue < 0 {
+		return fmt.Errorf("%w: expected revenue cannot be negative", types.ErrInvalidLeadConversion)
+	}
+	if req.Quotation != nil {
+		for i, line := range req.Quotation.Lines {
+			if strings.TrimSpace(line.Name) == "" {
+				return fmt.Errorf("%w: quotation line %d needs a name", types.ErrInvalidLeadConversion, i+1)
+			}
+			if line.Quantity <= 0 || line.PriceUnit < 0 {
+				return fmt.Errorf("%w: quotation line %d needs a positive quantity and a price of at least zero", types.ErrInvalidLeadConversion, i+1)
+			}
+		}
+	}
+	return nil
+}
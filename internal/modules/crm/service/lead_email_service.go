@@ -0,0 +1,456 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/email/inbound"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+// LeadEmailWebhookPath is the public path inbound email providers post to,
+// followed by the inbox token
+const LeadEmailWebhookPath = "/public/v1/inbound-email/"
+
+// maxLeadEmailSummary bounds the subject shown as the timeline activity
+const maxLeadEmailSummary = 255
+
+// LeadEmailService turns emails received on inbound mailboxes into leads and
+// keeps each lead's email conversation on its activity timeline
+type LeadEmailService struct {
+	repo        types.LeadEmailRepository
+	leadRepo    types.LeadRepository
+	leadService *LeadService
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	httpClient  *http.Client
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewLeadEmailService(repo types.LeadEmailRepository, leadRepo types.LeadRepository, leadService *LeadService, authService auth.LegacyAuthService, eventBus *events.Bus) *LeadEmailService {
+	return &LeadEmailService{
+		repo:        repo,
+		leadRepo:    leadRepo,
+		leadService: leadService,
+		authService: authService,
+		eventBus:    eventBus,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		logger:      slog.Default().With("service", "lead-email"),
+		now:         time.Now,
+	}
+}
+
+// ListInboxes lists the organization's inbound mailboxes
+func (s *LeadEmailService) ListInboxes(ctx context.Context, orgID uuid.UUID) ([]types.LeadEmailInbox, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_email_inboxes:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	inboxes, err := s.repo.ListInboxes(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inboxes {
+		inboxes[i].WebhookPath = LeadEmailWebhookPath + inboxes[i].Token
+	}
+	return inboxes, nil
+}
+
+// CreateInbox adds an inbound mailbox with a new webhook token
+func (s *LeadEmailService) CreateInbox(ctx context.Context, orgID uuid.UUID, req types.LeadEmailInboxRequest) (*types.LeadEmailInbox, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_email_inboxes:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	now := s.now()
+	inbox := types.LeadEmailInbox{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		inbox.CreatedBy = &userID
+		inbox.UpdatedBy = &userID
+	}
+	req.RotateToken = true
+	if err := s.applyInboxRequest(&inbox, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateInbox(ctx, inbox)
+	if err != nil {
+		return nil, err
+	}
+	created.WebhookPath = LeadEmailWebhookPath + created.Token
+
+	s.logger.Info("Created lead email inbox", "inbox_id", created.ID, "provider", created.Provider)
+	return created, nil
+}
+
+// UpdateInbox changes an inbound mailbox, issuing a new webhook token when asked
+func (s *LeadEmailService) UpdateInbox(ctx context.Context, orgID, id uuid.UUID, req types.LeadEmailInboxRequest) (*types.LeadEmailInbox, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_email_inboxes:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	inbox, err := s.repo.FindInbox(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	inbox.UpdatedAt = s.now()
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		inbox.UpdatedBy = &userID
+	}
+	if err := s.applyInboxRequest(inbox, req); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateInbox(ctx, *inbox)
+	if err != nil {
+		return nil, err
+	}
+	updated.WebhookPath = LeadEmailWebhookPath + updated.Token
+	return updated, nil
+}
+
+// DeleteInbox removes an inbound mailbox; the emails it received stay on
+// their leads
+func (s *LeadEmailService) DeleteInbox(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_email_inboxes:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+
+	return s.repo.DeleteInbox(ctx, orgID, id)
+}
+
+func (s *LeadEmailService) applyInboxRequest(inbox *types.LeadEmailInbox, req types.LeadEmailInboxRequest) error {
+	if req.Name != nil {
+		inbox.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Address != nil {
+		address := strings.ToLower(strings.TrimSpace(*req.Address))
+		inbox.Address = &address
+		if address == "" {
+			inbox.Address = nil
+		}
+	}
+	if req.Provider != nil {
+		inbox.Provider = *req.Provider
+	}
+	if req.SigningKey != nil {
+		key := strings.TrimSpace(*req.SigningKey)
+		inbox.SigningKey = &key
+		if key == "" {
+			inbox.SigningKey = nil
+		}
+	}
+	if req.SourceID != nil {
+		inbox.SourceID = req.SourceID
+	}
+	if req.TeamID != nil {
+		inbox.TeamID = req.TeamID
+	}
+	if req.Active != nil {
+		inbox.Active = *req.Active
+	}
+	if req.RotateToken {
+		token, err := newLeadEmailToken()
+		if err != nil {
+			return err
+		}
+		inbox.Token = token
+	}
+
+	switch {
+	case inbox.Name == "":
+		return fmt.Errorf("%w: name is required", types.ErrInvalidLeadEmailInbox)
+	case len(inbox.Name) > 255:
+		return fmt.Errorf("%w: name must be 255 characters or less", types.ErrInvalidLeadEmailInbox)
+	case !inbox.Provider.IsValid():
+		return fmt.Errorf("%w: provider must be sendgrid, mailgun or ses", types.ErrInvalidLeadEmailInbox)
+	case inbox.SigningKey != nil && inbox.Provider != inbound.Mailgun:
+		return fmt.Errorf("%w: only mailgun inboxes take a signing key", types.ErrInvalidLeadEmailInbox)
+	}
+	return nil
+}
+
+func newLeadEmailToken() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate inbox token: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// ListLeadEmails returns a lead's email threads, most recently active first
+func (s *LeadEmailService) ListLeadEmails(ctx context.Context, orgID, leadID uuid.UUID) ([]types.LeadEmailThread, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.leadService.GetLead(ctx, orgID, leadID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.ListByLead(ctx, orgID, leadID)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := []types.LeadEmailThread{}
+	index := make(map[uuid.UUID]int)
+	for _, msg := range messages {
+		i, ok := index[msg.ThreadID]
+		if !ok {
+			i = len(threads)
+			index[msg.ThreadID] = i
+			threads = append(threads, types.LeadEmailThread{ThreadID: msg.ThreadID, Subject: msg.Subject})
+		}
+		threads[i].Messages = append(threads[i].Messages, msg)
+		threads[i].MessageCount++
+		threads[i].LastMessageAt = msg.ReceivedAt
+	}
+	for i := 1; i < len(threads); i++ {
+		for j := i; j > 0 && threads[j].LastMessageAt.After(threads[j-1].LastMessageAt); j-- {
+			threads[j], threads[j-1] = threads[j-1], threads[j]
+		}
+	}
+	return threads, nil
+}
+
+// Receive handles a provider's webhook post to an inbox. A reply joins the
+// thread and lead of the message it answers; any other email is attached to
+// the sender's open lead or creates a lead, except auto-replies, which never
+// create one. The email appears as a done email activity on the lead.
+func (s *LeadEmailService) Receive(ctx context.Context, token, contentType string, body []byte) (*types.LeadEmailResult, error) {
+	inbox, err := s.repo.FindInboxByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var signingKey string
+	if inbox.SigningKey != nil {
+		signingKey = *inbox.SigningKey
+	}
+	msg, subscribeURL, err := inbound.Parse(inbox.Provider, contentType, body, signingKey, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if subscribeURL != "" {
+		if err := s.confirmSubscription(ctx, subscribeURL); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Confirmed lead email SNS subscription", "inbox_id", inbox.ID)
+		return &types.LeadEmailResult{Outcome: types.LeadEmailOutcomeSubscribed}, nil
+	}
+	if inbox.Address != nil && msg.From == *inbox.Address {
+		// Mail the inbox sent to itself would loop back into new leads
+		return &types.LeadEmailResult{Outcome: types.LeadEmailOutcomeIgnored}, nil
+	}
+
+	orgID := inbox.OrganizationID
+	if msg.MessageID != "" {
+		existing, err := s.repo.FindMessage(ctx, orgID, msg.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return leadEmailResult(types.LeadEmailOutcomeDuplicate, existing), nil
+		}
+	}
+
+	record := types.LeadEmailMessage{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		InboxID:        &inbox.ID,
+		References:     msg.References,
+		FromEmail:      msg.From,
+		ToEmails:       msg.To,
+		Subject:        msg.Subject,
+		ReceivedAt:     msg.Date,
+		CreatedAt:      s.now(),
+	}
+	record.ThreadID = record.ID
+	if record.ReceivedAt.IsZero() || record.ReceivedAt.After(record.CreatedAt) {
+		record.ReceivedAt = record.CreatedAt
+	}
+	record.MessageID = optionalString(msg.MessageID)
+	record.InReplyTo = optionalString(msg.InReplyTo)
+	record.FromName = optionalString(msg.FromName)
+	record.BodyText = optionalString(msg.Text)
+	record.BodyHTML = optionalString(msg.HTML)
+
+	outcome := types.LeadEmailOutcomeThreaded
+	replied, err := s.repo.FindReplied(ctx, orgID, msg.Thread())
+	if err != nil {
+		return nil, err
+	}
+	if replied != nil {
+		record.LeadID = replied.LeadID
+		record.ThreadID = replied.ThreadID
+	} else {
+		lead, err := s.leadRepo.FindOpenByEmailSince(ctx, orgID, msg.From, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to match lead: %w", err)
+		}
+		switch {
+		case lead != nil:
+			outcome = types.LeadEmailOutcomeMatched
+			record.LeadID = lead.ID
+		case msg.AutoSubmitted:
+			return &types.LeadEmailResult{Outcome: types.LeadEmailOutcomeIgnored}, nil
+		default:
+			created, err := s.createLead(ctx, inbox, msg)
+			if err != nil {
+				return nil, err
+			}
+			outcome = types.LeadEmailOutcomeCreated
+			record.LeadID = created.ID
+		}
+	}
+
+	stored, err := s.repo.CreateMessage(ctx, record, leadEmailActivity(record, msg))
+	if errors.Is(err, types.ErrLeadEmailDuplicate) && msg.MessageID != "" {
+		// A concurrent retry of the same message won the race
+		existing, findErr := s.repo.FindMessage(ctx, orgID, msg.MessageID)
+		if findErr != nil || existing == nil {
+			return nil, err
+		}
+		return leadEmailResult(types.LeadEmailOutcomeDuplicate, existing), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Received lead email", "inbox_id", inbox.ID, "lead_id", stored.LeadID, "outcome", outcome)
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.email_received", map[string]interface{}{
+			"organization_id": orgID,
+			"lead_id":         stored.LeadID,
+			"message_id":      stored.ID,
+			"thread_id":       stored.ThreadID,
+			"outcome":         outcome,
+		})
+	}
+	return leadEmailResult(outcome, stored), nil
+}
+
+// createLead creates the lead for an email from a new sender, named after
+// the subject and carrying the inbox's source and team
+func (s *LeadEmailService) createLead(ctx context.Context, inbox *types.LeadEmailInbox, msg *inbound.Message) (types.Lead, error) {
+	name := msg.Subject
+	if name == "" {
+		name = "Email from " + msg.From
+	}
+	if len(name) > maxLeadEmailSummary {
+		name = truncateUTF8(name, maxLeadEmailSummary)
+	}
+
+	req := types.LeadCreateRequest{
+		Name:     name,
+		Email:    &msg.From,
+		SourceID: inbox.SourceID,
+		TeamID:   inbox.TeamID,
+		Active:   true,
+	}
+	if msg.FromName != "" {
+		req.ContactName = &msg.FromName
+	}
+	if description := inbound.StripReply(msg.Text); description != "" {
+		req.Description = &description
+	}
+
+	lead, err := s.leadService.CreateLead(ctx, inbox.OrganizationID, req)
+	if err != nil {
+		return types.Lead{}, fmt.Errorf("failed to create lead from email: %w", err)
+	}
+	return lead, nil
+}
+
+// leadEmailActivity is the done email activity showing a received message on
+// its lead's timeline
+func leadEmailActivity(record types.LeadEmailMessage, msg *inbound.Message) types.Activity {
+	summary := msg.Subject
+	if summary == "" {
+		summary = "(no subject)"
+	}
+	resModel := "leads"
+	activity := types.Activity{
+		ID:             uuid.New(),
+		OrganizationID: record.OrganizationID,
+		ActivityType:   types.ActivityTypeEmail,
+		Summary:        truncateUTF8("Email from "+msg.From+": "+summary, maxLeadEmailSummary),
+		ResModel:       &resModel,
+		ResID:          &record.LeadID,
+		State:          types.ActivityStateDone,
+		DoneDate:       &record.ReceivedAt,
+		CreatedAt:      record.CreatedAt,
+		UpdatedAt:      record.CreatedAt,
+	}
+	if note := inbound.StripReply(msg.Text); note != "" {
+		activity.Note = &note
+	}
+	return activity
+}
+
+func leadEmailResult(outcome types.LeadEmailOutcome, msg *types.LeadEmailMessage) *types.LeadEmailResult {
+	return &types.LeadEmailResult{
+		Outcome:   outcome,
+		LeadID:    &msg.LeadID,
+		MessageID: &msg.ID,
+		ThreadID:  &msg.ThreadID,
+	}
+}
+
+// confirmSubscription confirms an SNS subscription; the URL was checked to
+// be an SNS endpoint when parsed
+func (s *LeadEmailService) confirmSubscription(ctx context.Context, subscribeURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm SNS subscription: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
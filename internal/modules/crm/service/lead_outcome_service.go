@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// SetOutcomes enables marking leads won or lost
+func (s *LeadService) SetOutcomes(outcomes types.LeadOutcomeRepository) {
+	s.outcomes = outcomes
+}
+
+// checkLostReason enforces that a lead marked lost, through its status or
+// its won status, says why
+func checkLostReason(lead types.Lead) error {
+	lost := lead.Status == types.LeadStatusLost ||
+		(lead.WonStatus != nil && *lead.WonStatus == types.LeadWonStatusLost)
+	if lost && lead.LostReasonID == nil {
+		return types.ErrLostReasonRequired
+	}
+	return nil
+}
+
+// MarkWon closes an open lead as won, recording when it closed
+func (s *LeadService) MarkWon(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadMarkWonRequest) (*types.LeadOutcome, error) {
+	if req.ExpectedRevenue != nil && *req.ExpectedRevenue < 0 {
+		return nil, fmt.Errorf("%w: expected revenue cannot be negative", types.ErrInvalidLeadOutcome)
+	}
+	return s.closeLead(ctx, types.LeadOutcome{
+		LeadID:          id,
+		OrganizationID:  orgID,
+		WonStatus:       types.LeadWonStatusWon,
+		ExpectedRevenue: req.ExpectedRevenue,
+		Note:            req.Note,
+	}, "crm.lead.won")
+}
+
+// MarkLost closes an open lead as lost for one of the organization's
+// active lost reasons, with an optional note
+func (s *LeadService) MarkLost(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadMarkLostRequest) (*types.LeadOutcome, error) {
+	if req.LostReasonID == nil || *req.LostReasonID == uuid.Nil {
+		return nil, types.ErrLostReasonRequired
+	}
+	return s.closeLead(ctx, types.LeadOutcome{
+		LeadID:         id,
+		OrganizationID: orgID,
+		WonStatus:      types.LeadWonStatusLost,
+		LostReasonID:   req.LostReasonID,
+		Note:           req.Note,
+	}, "crm.lead.lost")
+}
+
+func (s *LeadService) closeLead(ctx context.Context, outcome types.LeadOutcome, event string) (*types.LeadOutcome, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.outcomes == nil {
+		return nil, errors.New("lead outcomes are not available")
+	}
+
+	if outcome.Note != nil {
+		note := strings.TrimSpace(*outcome.Note)
+		outcome.Note = &note
+		if note == "" {
+			outcome.Note = nil
+		}
+	}
+	outcome.ClosedAt = time.Now()
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		outcome.ClosedBy = &userID
+	}
+
+	closed, err := s.outcomes.Close(ctx, outcome)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, event, closed)
+	}
+
+	return closed, nil
+}
+
+// GetProbabilityAccuracy reports how well the stage probabilities and the
+// manual ones of closed leads forecast their outcome
+func (s *LeadService) GetProbabilityAccuracy(ctx context.Context, orgID uuid.UUID, filter types.LeadProbabilityAccuracyFilter) (*types.LeadProbabilityAccuracy, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.outcomes == nil {
+		return nil, errors.New("lead outcomes are not available")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		return nil, errors.New("date_to must be after date_from")
+	}
+
+	filter.OrganizationID = orgID
+	return s.outcomes.ProbabilityAccuracy(ctx, filter)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// LeadPipelineSnapshotInterval is how often the snapshot job looks for a
+// week not yet captured. Checking daily still takes a week's snapshot on its
+// first day when a pass is missed.
+const LeadPipelineSnapshotInterval = 24 * time.Hour
+
+// SetPipelineSnapshots enables weekly pipeline snapshots and comparing them
+func (s *LeadService) SetPipelineSnapshots(snapshots types.LeadPipelineSnapshotRepository) {
+	s.pipelineSnapshots = snapshots
+}
+
+// SnapshotPipeline records the open pipeline of every organization for the
+// week of now. Organizations already captured that week are left as they
+// were, so passes within a week add nothing.
+func (s *LeadService) SnapshotPipeline(ctx context.Context, now time.Time) (*types.LeadPipelineSnapshotRun, error) {
+	if s.pipelineSnapshots == nil {
+		return nil, errors.New("pipeline snapshots are not available")
+	}
+	return s.pipelineSnapshots.Capture(ctx, types.PipelineSnapshotWeek(now))
+}
+
+// ComparePipeline compares the latest pipeline snapshot on or before the
+// given week, this week by default, with the snapshot of the week before.
+// A team narrows the comparison to that team's stages.
+func (s *LeadService) ComparePipeline(ctx context.Context, orgID uuid.UUID, week *time.Time, teamID *uuid.UUID) (*types.LeadPipelineComparison, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.pipelineSnapshots == nil {
+		return nil, errors.New("pipeline snapshots are not available")
+	}
+
+	asOf := time.Now()
+	if week != nil {
+		asOf = *week
+	}
+	weekStart, err := s.pipelineSnapshots.LatestWeek(ctx, orgID, types.PipelineSnapshotWeek(asOf))
+	if err != nil {
+		return nil, err
+	}
+	previousWeekStart := weekStart.AddDate(0, 0, -7)
+
+	current, err := s.pipelineSnapshots.Totals(ctx, orgID, weekStart, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []types.LeadPipelineSnapshotTotal
+	var movement []types.LeadPipelineStageMovement
+	latest, err := s.pipelineSnapshots.LatestWeek(ctx, orgID, previousWeekStart)
+	hasPrevious := err == nil && latest.Equal(previousWeekStart)
+	if err != nil && !errors.Is(err, types.ErrNoPipelineSnapshot) {
+		return nil, err
+	}
+	if hasPrevious {
+		if previous, err = s.pipelineSnapshots.Totals(ctx, orgID, previousWeekStart, teamID); err != nil {
+			return nil, err
+		}
+		if movement, err = s.pipelineSnapshots.Movement(ctx, orgID, weekStart, previousWeekStart, teamID); err != nil {
+			return nil, err
+		}
+	}
+
+	comparison := BuildLeadPipelineComparison(current, previous, movement)
+	comparison.WeekStart = weekStart
+	comparison.PreviousWeekStart = previousWeekStart
+	comparison.TeamID = teamID
+	comparison.HasPrevious = hasPrevious
+	return comparison, nil
+}
+
+// pipelineStageKey is a team's stage; nil IDs are the leads without one
+type pipelineStageKey struct {
+	teamID, stageID uuid.UUID
+}
+
+func newPipelineStageKey(teamID, stageID *uuid.UUID) pipelineStageKey {
+	var key pipelineStageKey
+	if teamID != nil {
+		key.teamID = *teamID
+	}
+	if stageID != nil {
+		key.stageID = *stageID
+	}
+	return key
+}
+
+// BuildLeadPipelineComparison lines up the totals of two weeks and the
+// movement between them per team and stage, ordered by team name, then stage
+// sequence with stages lacking one last, then stage name. Names and
+// sequences come from the current week when the stage is in both.
+func BuildLeadPipelineComparison(current, previous []types.LeadPipelineSnapshotTotal, movement []types.LeadPipelineStageMovement) *types.LeadPipelineComparison {
+	comparison := &types.LeadPipelineComparison{Stages: []types.LeadPipelineStageComparison{}}
+	index := make(map[pipelineStageKey]int)
+	stage := func(teamID, stageID *uuid.UUID) *types.LeadPipelineStageComparison {
+		key := newPipelineStageKey(teamID, stageID)
+		if i, ok := index[key]; ok {
+			return &comparison.Stages[i]
+		}
+		index[key] = len(comparison.Stages)
+		comparison.Stages = append(comparison.Stages, types.LeadPipelineStageComparison{TeamID: teamID, StageID: stageID})
+		return &comparison.Stages[len(comparison.Stages)-1]
+	}
+
+	for _, total := range current {
+		s := stage(total.TeamID, total.StageID)
+		s.TeamName, s.StageName, s.StageSequence = total.TeamName, total.StageName, total.StageSequence
+		s.Current = total.LeadPipelineTotals
+		addPipelineTotals(&comparison.Current, total.LeadPipelineTotals)
+	}
+	for _, total := range previous {
+		s := stage(total.TeamID, total.StageID)
+		if s.TeamName == "" && s.StageName == "" && s.StageSequence == nil {
+			s.TeamName, s.StageName, s.StageSequence = total.TeamName, total.StageName, total.StageSequence
+		}
+		s.Previous = total.LeadPipelineTotals
+		addPipelineTotals(&comparison.Previous, total.LeadPipelineTotals)
+	}
+	for _, m := range movement {
+		stage(m.TeamID, m.StageID).Movement = m.LeadPipelineMovement
+	}
+
+	sort.SliceStable(comparison.Stages, func(i, j int) bool {
+		a, b := comparison.Stages[i], comparison.Stages[j]
+		if a.TeamName != b.TeamName {
+			return a.TeamName < b.TeamName
+		}
+		if (a.StageSequence == nil) != (b.StageSequence == nil) {
+			return b.StageSequence == nil
+		}
+		if a.StageSequence != nil && *a.StageSequence != *b.StageSequence {
+			return *a.StageSequence < *b.StageSequence
+		}
+		return a.StageName < b.StageName
+	})
+	return comparison
+}
+
+func addPipelineTotals(sum *types.LeadPipelineTotals, totals types.LeadPipelineTotals) {
+	sum.Count += totals.Count
+	sum.Value += totals.Value
+	sum.WeightedValue += totals.WeightedValue
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestPipelineSnapshotWeekStartsOnMonday(t *testing.T) {
+	monday := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, types.PipelineSnapshotWeek(monday))
+	assert.Equal(t, monday, types.PipelineSnapshotWeek(time.Date(2025, 7, 16, 15, 30, 0, 0, time.UTC)))
+	assert.Equal(t, monday, types.PipelineSnapshotWeek(time.Date(2025, 7, 20, 23, 59, 0, 0, time.UTC)))
+
+	// Monday 01:00 in Paris is still Sunday in UTC
+	paris := time.FixedZone("CEST", 2*60*60)
+	assert.Equal(t, monday.AddDate(0, 0, -7), types.PipelineSnapshotWeek(time.Date(2025, 7, 14, 1, 0, 0, 0, paris)))
+}
+
+func TestBuildLeadPipelineComparisonMergesWeeks(t *testing.T) {
+	sales, support := uuid.New(), uuid.New()
+	qualified, proposal, won := uuid.New(), uuid.New(), uuid.New()
+	one, two := 1, 2
+
+	current := []types.LeadPipelineSnapshotTotal{
+		{TeamID: &support, TeamName: "Support", StageID: &qualified, StageName: "Qualified", StageSequence: &one,
+			LeadPipelineTotals: types.LeadPipelineTotals{Count: 1, Value: 100, WeightedValue: 10}},
+		{TeamID: &sales, TeamName: "Sales", StageID: &proposal, StageName: "Proposal", StageSequence: &two,
+			LeadPipelineTotals: types.LeadPipelineTotals{Count: 2, Value: 2000, WeightedValue: 1200}},
+		{TeamID: &sales, TeamName: "Sales",
+			LeadPipelineTotals: types.LeadPipelineTotals{Count: 1, Value: 50, WeightedValue: 0}},
+	}
+	previous := []types.LeadPipelineSnapshotTotal{
+		{TeamID: &sales, TeamName: "Sales", StageID: &qualified, StageName: "Qualified", StageSequence: &one,
+			LeadPipelineTotals: types.LeadPipelineTotals{Count: 3, Value: 3000, WeightedValue: 600}},
+		{TeamID: &sales, TeamName: "Sales", StageID: &won, StageName: "Old name", StageSequence: &two,
+			LeadPipelineTotals: types.LeadPipelineTotals{Count: 1, Value: 10, WeightedValue: 5}},
+	}
+	movement := []types.LeadPipelineStageMovement{
+		{TeamID: &sales, StageID: &qualified, LeadPipelineMovement: types.LeadPipelineMovement{Exited: 3, Advanced: 2, Closed: 1}},
+		{TeamID: &sales, StageID: &proposal, LeadPipelineMovement: types.LeadPipelineMovement{Entered: 2}},
+	}
+
+	comparison := BuildLeadPipelineComparison(current, previous, movement)
+	assert.Equal(t, types.LeadPipelineTotals{Count: 4, Value: 2150, WeightedValue: 1210}, comparison.Current)
+	assert.Equal(t, types.LeadPipelineTotals{Count: 4, Value: 3010, WeightedValue: 605}, comparison.Previous)
+
+	require.Len(t, comparison.Stages, 5)
+	names := make([]string, len(comparison.Stages))
+	for i, s := range comparison.Stages {
+		names[i] = s.TeamName + "/" + s.StageName
+	}
+	// Sales stages by sequence, the proposal before the old stage on a tie by
+	// name, and the leads without a stage last
+	assert.Equal(t, []string{"Sales/Qualified", "Sales/Old name", "Sales/Proposal", "Sales/", "Support/Qualified"}, names)
+
+	qualifiedSales := comparison.Stages[0]
+	assert.Zero(t, qualifiedSales.Current)
+	assert.Equal(t, 3, qualifiedSales.Previous.Count)
+	assert.Equal(t, types.LeadPipelineMovement{Exited: 3, Advanced: 2, Closed: 1}, qualifiedSales.Movement)
+
+	proposalSales := comparison.Stages[2]
+	assert.Equal(t, 2, proposalSales.Current.Count)
+	assert.Zero(t, proposalSales.Previous)
+	assert.Equal(t, 2, proposalSales.Movement.Entered)
+}
+
+func TestBuildLeadPipelineComparisonWithoutPreviousWeek(t *testing.T) {
+	comparison := BuildLeadPipelineComparison(nil, nil, nil)
+	assert.NotNil(t, comparison.Stages)
+	assert.Empty(t, comparison.Stages)
+	assert.Zero(t, comparison.Current)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxLeadReassignReason bounds the reason kept in the assignment history
+	maxLeadReassignReason = 500
+	// reassignPushTimeout bounds a push notification to a new owner
+	reassignPushTimeout = 15 * time.Second
+)
+
+// SetReassignment enables transferring a user's open leads. Loads give the
+// new owners' capacity and availability; teams give the members leads are
+// round-robined across.
+func (s *LeadService) SetReassignment(reassign types.LeadReassignRepository, loads types.AssignmentEngineRepository, teams types.SalesTeamRepository) {
+	s.reassign = reassign
+	s.assignmentLoads = loads
+	s.teams = teams
+}
+
+// ReassignLeads transfers the open leads of a user to another user, or
+// round-robins them across the available members of a team, without taking
+// any new owner past their max capacity. Leads no one has room for stay
+// with their owner. Each new owner is told which leads they were handed.
+func (s *LeadService) ReassignLeads(ctx context.Context, orgID uuid.UUID, req types.LeadReassignRequest) (*types.LeadReassignResult, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.reassign == nil || s.assignmentLoads == nil || s.teams == nil {
+		return nil, errors.New("lead reassignment is not available")
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.FromUserID == uuid.Nil:
+		return nil, fmt.Errorf("%w: from_user_id is required", types.ErrInvalidLeadReassignment)
+	case (req.ToUserID == nil) == (req.TeamID == nil):
+		return nil, fmt.Errorf("%w: give either to_user_id or team_id", types.ErrInvalidLeadReassignment)
+	case req.ToUserID != nil && *req.ToUserID == req.FromUserID:
+		return nil, fmt.Errorf("%w: leads are already assigned to %s", types.ErrInvalidLeadReassignment, req.FromUserID)
+	case len(req.Reason) > maxLeadReassignReason:
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", types.ErrInvalidLeadReassignment, maxLeadReassignReason)
+	}
+
+	candidates, err := s.reassignCandidates(ctx, orgID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more than allowed tells whether the user has leads left over
+	leads, err := s.reassign.OpenLeads(ctx, orgID, req.FromUserID, types.MaxLeadBulkSize+1)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.LeadReassignResult{Results: []types.LeadReassignItemResult{}}
+	if len(leads) > types.MaxLeadBulkSize {
+		leads = leads[:types.MaxLeadBulkSize]
+		result.HasMore = true
+	}
+	result.Matched = len(leads)
+
+	assignments, _ := PlanLeadReassignment(leads, candidates)
+	batch := types.LeadReassignBatch{
+		OrganizationID: orgID,
+		FromUserID:     req.FromUserID,
+		Assignments:    assignments,
+		Reason:         req.Reason,
+		AssignedAt:     time.Now(),
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		batch.AssignedBy = &userID
+	}
+
+	var reassigned []uuid.UUID
+	if len(assignments) > 0 {
+		reassigned, err = s.reassign.Reassign(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	done := make(map[uuid.UUID]bool, len(reassigned))
+	for _, id := range reassigned {
+		done[id] = true
+	}
+	planned := make(map[uuid.UUID]uuid.UUID, len(assignments))
+	for _, a := range assignments {
+		planned[a.LeadID] = a.ToUserID
+	}
+	handed := make(map[uuid.UUID][]uuid.UUID)
+	var owners []uuid.UUID
+	for _, lead := range leads {
+		item := types.LeadReassignItemResult{LeadID: lead.ID}
+		owner, ok := planned[lead.ID]
+		switch {
+		case !ok:
+			item.Status = types.LeadReassignStatusOverCapacity
+			result.OverCapacity++
+		case done[lead.ID]:
+			item.Status = types.LeadReassignStatusReassigned
+			item.AssignedTo = &owner
+			result.Reassigned++
+			if _, seen := handed[owner]; !seen {
+				owners = append(owners, owner)
+			}
+			handed[owner] = append(handed[owner], lead.ID)
+		default:
+			item.Status = types.LeadReassignStatusFailed
+			item.Error = "lead changed owner or was closed meanwhile"
+			result.Failed++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	for _, owner := range owners {
+		notice := types.LeadReassignNotice{
+			OrganizationID: orgID,
+			UserID:         owner,
+			FromUserID:     req.FromUserID,
+			LeadIDs:        handed[owner],
+			Reason:         req.Reason,
+			AssignedBy:     batch.AssignedBy,
+			AssignedAt:     batch.AssignedAt,
+		}
+		if s.eventBus != nil {
+			s.eventBus.Publish(ctx, "crm.lead.reassigned", notice)
+		}
+		if s.notifier != nil {
+			s.notifyReassignment(ctx, notice)
+		}
+	}
+
+	return result, nil
+}
+
+// reassignCandidates returns the loads of the users leads may go to: the
+// target user, or the team's available members other than the previous
+// owner
+func (s *LeadService) reassignCandidates(ctx context.Context, orgID uuid.UUID, req types.LeadReassignRequest) ([]types.UserAssignmentLoad, error) {
+	if req.ToUserID != nil {
+		return s.assignmentLoads.ListCandidateLoads(ctx, orgID, string(types.AssignmentTargetModelLeads), []uuid.UUID{*req.ToUserID})
+	}
+
+	team, err := s.teams.FindByID(ctx, *req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.OrganizationID != orgID || team.DeletedAt != nil {
+		return nil, errors.New("sales team not found or access denied")
+	}
+	var members []uuid.UUID
+	for _, id := range team.MemberIDs {
+		if id != req.FromUserID {
+			members = append(members, id)
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%w: team %s has no other members", types.ErrInvalidLeadReassignment, team.Name)
+	}
+
+	loads, err := s.assignmentLoads.ListCandidateLoads(ctx, orgID, string(types.AssignmentTargetModelLeads), members)
+	if err != nil {
+		return nil, err
+	}
+	available := loads[:0]
+	for _, load := range loads {
+		if load.IsAvailable {
+			available = append(available, load)
+		}
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("%w: no member of team %s is available", types.ErrInvalidLeadReassignment, team.Name)
+	}
+	return available, nil
+}
+
+// PlanLeadReassignment deals the leads round-robin to the candidates, the
+// least loaded first, skipping those at their max capacity (0 is
+// unlimited). It returns the assignments and the leads no candidate had
+// room for.
+func PlanLeadReassignment(leads []types.LeadReassignTarget, candidates []types.UserAssignmentLoad) ([]types.LeadReassignment, []types.LeadReassignTarget) {
+	order := append([]types.UserAssignmentLoad(nil), candidates...)
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].ActiveAssignments != order[j].ActiveAssignments {
+			return order[i].ActiveAssignments < order[j].ActiveAssignments
+		}
+		return order[i].LastAssignedAt.Before(order[j].LastAssignedAt)
+	})
+
+	// room is how many more leads each candidate takes; -1 is unlimited
+	room := make([]int, len(order))
+	for i, load := range order {
+		room[i] = -1
+		if load.MaxCapacity > 0 {
+			room[i] = max(load.MaxCapacity-load.ActiveAssignments, 0)
+		}
+	}
+
+	assignments := make([]types.LeadReassignment, 0, len(leads))
+	next := 0
+	for i, lead := range leads {
+		chosen := -1
+		for tried := 0; tried < len(order); tried++ {
+			candidate := (next + tried) % len(order)
+			if room[candidate] != 0 {
+				chosen = candidate
+				break
+			}
+		}
+		if chosen < 0 {
+			return assignments, leads[i:]
+		}
+		if room[chosen] > 0 {
+			room[chosen]--
+		}
+		assignments = append(assignments, types.LeadReassignment{
+			LeadID:   lead.ID,
+			LeadName: lead.Name,
+			ToUserID: order[chosen].UserID,
+		})
+		next = (chosen + 1) % len(order)
+	}
+	return assignments, nil
+}
+
+// notifyReassignment tells a new owner how many leads they were handed.
+// Push failures do not undo the reassignment and are not reported.
+func (s *LeadService) notifyReassignment(ctx context.Context, notice types.LeadReassignNotice) {
+	title, body := "Lead assigned to you", "A lead was reassigned to you"
+	if len(notice.LeadIDs) > 1 {
+		title, body = "Leads assigned to you", fmt.Sprintf("%d leads were reassigned to you", len(notice.LeadIDs))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reassignPushTimeout)
+	defer cancel()
+	s.notifier.Send(ctx, push.Notification{
+		OrganizationID: notice.OrganizationID,
+		UserIDs:        []uuid.UUID{notice.UserID},
+		Title:          title,
+		Body:           body,
+		Data: map[string]string{
+			"type":  "leads_reassigned",
+			"count": fmt.Sprint(len(notice.LeadIDs)),
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func reassignTargets(n int) []types.LeadReassignTarget {
+	leads := make([]types.LeadReassignTarget, n)
+	for i := range leads {
+		leads[i] = types.LeadReassignTarget{ID: uuid.New(), Name: "Lead"}
+	}
+	return leads
+}
+
+func TestPlanLeadReassignmentRoundRobinsWithinCapacity(t *testing.T) {
+	now := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC)
+	busy, idle, unlimited := uuid.New(), uuid.New(), uuid.New()
+	candidates := []types.UserAssignmentLoad{
+		{UserID: busy, ActiveAssignments: 9, MaxCapacity: 10, LastAssignedAt: now},
+		{UserID: unlimited, ActiveAssignments: 4, LastAssignedAt: now},
+		{UserID: idle, ActiveAssignments: 0, MaxCapacity: 2, LastAssignedAt: now.Add(-time.Hour)},
+	}
+	leads := reassignTargets(7)
+
+	assignments, left := PlanLeadReassignment(leads, candidates)
+	assert.Empty(t, left)
+	require.Len(t, assignments, 7)
+
+	owners := make([]uuid.UUID, len(assignments))
+	for i, a := range assignments {
+		assert.Equal(t, leads[i].ID, a.LeadID)
+		owners[i] = a.ToUserID
+	}
+	// The least loaded go first; full candidates drop out of the rotation
+	assert.Equal(t, []uuid.UUID{idle, unlimited, busy, idle, unlimited, unlimited, unlimited}, owners)
+}
+
+func TestPlanLeadReassignmentLeavesLeadsOverCapacity(t *testing.T) {
+	full, nearlyFull := uuid.New(), uuid.New()
+	candidates := []types.UserAssignmentLoad{
+		{UserID: full, ActiveAssignments: 6, MaxCapacity: 5},
+		{UserID: nearlyFull, ActiveAssignments: 3, MaxCapacity: 4},
+	}
+	leads := reassignTargets(3)
+
+	assignments, left := PlanLeadReassignment(leads, candidates)
+	require.Len(t, assignments, 1)
+	assert.Equal(t, nearlyFull, assignments[0].ToUserID)
+	assert.Equal(t, leads[1:], left)
+}
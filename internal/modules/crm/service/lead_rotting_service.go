@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LeadRottingInterval is how often leads are checked for rotting
+	LeadRottingInterval = time.Hour
+	// leadRottingBatchSize bounds the leads rotated per pass
+	leadRottingBatchSize = 500
+	// rottingPushTimeout bounds a push notification to a team leader
+	rottingPushTimeout = 15 * time.Second
+	// rottingNoticeNames is how many lead names a notification lists
+	rottingNoticeNames = 3
+)
+
+// rottingConditions selects the assignment rules that take over rotting
+// leads. A pool rule returns them to the pool of unassigned leads.
+var rottingConditions = map[string]interface{}{"rotting": true}
+
+// SetRotting enables rotating the leads left untouched past their stage's
+// rotting days
+func (s *LeadService) SetRotting(rotting types.LeadRottingRepository) {
+	s.rotting = rotting
+}
+
+// SetPushNotifier sets the notifier telling team leaders which of their
+// team's leads were rotated
+func (s *LeadService) SetPushNotifier(notifier push.Notifier) {
+	s.notifier = notifier
+}
+
+// GetRotationStats reports, per stage, how many leads rotted and what became
+// of them
+func (s *LeadService) GetRotationStats(ctx context.Context, orgID uuid.UUID, filter types.LeadRotationStatsFilter) ([]types.LeadRotationStats, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.rotting == nil {
+		return nil, errors.New("lead rotting is not available")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		return nil, errors.New("date_to must be after date_from")
+	}
+
+	filter.OrganizationID = orgID
+	return s.rotting.RotationStats(ctx, filter)
+}
+
+// RotateRottingLeads hands the leads left untouched past their stage's
+// rotting days to the assignment rules matching rotting, which reassign them
+// or return them to the pool, and tells each team's leader which leads were
+// rotated. It is invoked by the scheduled job and therefore bypasses the
+// per-request permission check. Failures of single leads do not stop the
+// pass and are returned together.
+func (s *LeadService) RotateRottingLeads(ctx context.Context, now time.Time) (*types.LeadRottingRun, error) {
+	if s.rotting == nil {
+		return nil, errors.New("lead rotting is not available")
+	}
+	if s.assignmentRuleAssigner == nil {
+		return nil, errors.New("lead assignment is not available")
+	}
+
+	leads, err := s.rotting.RottingLeads(ctx, now, leadRottingBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var run types.LeadRottingRun
+	var errs []error
+	notices := make(map[rottingNoticeKey][]types.LeadRotation)
+	for _, lead := range leads {
+		rotation, err := s.rotateLead(ctx, lead, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lead %s: %w", lead.LeadID, err))
+		}
+		if err := s.rotting.RecordRotation(ctx, *rotation); err != nil {
+			errs = append(errs, fmt.Errorf("lead %s: %w", lead.LeadID, err))
+			continue
+		}
+
+		switch rotation.Result {
+		case types.LeadRotationReassigned:
+			run.Reassigned++
+		case types.LeadRotationPooled:
+			run.Pooled++
+		case types.LeadRotationKept:
+			run.Kept++
+		case types.LeadRotationNoRule:
+			run.NoRule++
+		case types.LeadRotationFailed:
+			run.Failed++
+			continue
+		}
+		run.Rotted++
+
+		if s.eventBus != nil {
+			s.eventBus.Publish(ctx, "crm.lead.rotted", *rotation)
+		}
+		if lead.TeamLeaderID != nil {
+			key := rottingNoticeKey{orgID: lead.OrganizationID, leaderID: *lead.TeamLeaderID}
+			notices[key] = append(notices[key], *rotation)
+		}
+	}
+
+	if s.notifier != nil {
+		for key, rotations := range notices {
+			if err := s.notifyRotations(ctx, key, rotations); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return &run, errors.Join(errs...)
+}
+
+// rotateLead rotates a lead through the assignment rules
+func (s *LeadService) rotateLead(ctx context.Context, lead types.RottingLead, now time.Time) (*types.LeadRotation, error) {
+	rotation := &types.LeadRotation{
+		ID:                 uuid.New(),
+		OrganizationID:     lead.OrganizationID,
+		LeadID:             lead.LeadID,
+		LeadName:           lead.LeadName,
+		StageID:            lead.StageID,
+		TeamID:             lead.TeamID,
+		PreviousAssignedTo: lead.AssignedTo,
+		RottingDays:        lead.RottingDays,
+		LastTouchedAt:      lead.LastTouchedAt,
+		RotatedAt:          now,
+	}
+
+	result, err := s.assignmentRuleAssigner.AssignLead(ctx, lead.LeadID, rottingConditions)
+	switch {
+	case errors.Is(err, types.ErrNoMatchingAssignmentRule):
+		rotation.Result = types.LeadRotationNoRule
+	case err != nil:
+		rotation.Result = types.LeadRotationFailed
+		return rotation, fmt.Errorf("failed to rotate lead: %w", err)
+	case result.Changed && result.AssignedToID == uuid.Nil:
+		rotation.Result = types.LeadRotationPooled
+	case result.Changed:
+		rotation.Result = types.LeadRotationReassigned
+		rotation.AssignedTo = &result.AssignedToID
+	default:
+		rotation.Result = types.LeadRotationKept
+		rotation.AssignedTo = &lead.AssignedTo
+	}
+	return rotation, nil
+}
+
+// rottingNoticeKey is a team leader of an organization
+type rottingNoticeKey struct {
+	orgID    uuid.UUID
+	leaderID uuid.UUID
+}
+
+// notifyRotations tells a team leader which of their team's leads rotted
+func (s *LeadService) notifyRotations(ctx context.Context, key rottingNoticeKey, rotations []types.LeadRotation) error {
+	names := make([]string, 0, rottingNoticeNames)
+	for _, rotation := range rotations {
+		if len(names) == rottingNoticeNames {
+			break
+		}
+		names = append(names, rotation.LeadName)
+	}
+	list := strings.Join(names, ", ")
+	if more := len(rotations) - len(names); more > 0 {
+		list += fmt.Sprintf(" and %d more", more)
+	}
+
+	title, body := "Lead rotated", "An untouched lead was rotated: "+list
+	if len(rotations) > 1 {
+		title, body = "Leads rotated", fmt.Sprintf("%d untouched leads were rotated: %s", len(rotations), list)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rottingPushTimeout)
+	defer cancel()
+	err := s.notifier.Send(ctx, push.Notification{
+		OrganizationID: key.orgID,
+		UserIDs:        []uuid.UUID{key.leaderID},
+		Title:          title,
+		Body:           body,
+		Data: map[string]string{
+			"type":  "leads_rotated",
+			"count": fmt.Sprint(len(rotations)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify team leader %s of rotated leads: %w", key.leaderID, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLeadSearchLimit is the page size of lead searches that set none
+	DefaultLeadSearchLimit = 20
+	// MaxLeadSearchLimit bounds the page size of lead searches
+	MaxLeadSearchLimit = 100
+	// MaxLeadSearchQueryLength bounds the length of lead search queries
+	MaxLeadSearchQueryLength = 200
+)
+
+// SetSearch enables full-text lead search
+func (s *LeadService) SetSearch(search types.LeadSearchRepository) {
+	s.search = search
+}
+
+// SearchLeads returns the organization's leads matching every word of the
+// query, most relevant first, with facet counts by stage and priority
+func (s *LeadService) SearchLeads(ctx context.Context, orgID uuid.UUID, req types.LeadSearchRequest) (*types.LeadSearchResult, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.search == nil {
+		return nil, errors.New("lead search is not available")
+	}
+
+	req.OrganizationID = orgID
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return nil, fmt.Errorf("%w: q is required", types.ErrInvalidLeadSearch)
+	}
+	if utf8.RuneCountInString(req.Query) > MaxLeadSearchQueryLength {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", types.ErrInvalidLeadSearch, MaxLeadSearchQueryLength)
+	}
+	if req.Priority != nil && !req.Priority.IsValid() {
+		return nil, fmt.Errorf("%w: unknown priority %q", types.ErrInvalidLeadSearch, *req.Priority)
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultLeadSearchLimit
+	}
+	if req.Limit < 0 || req.Limit > MaxLeadSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", types.ErrInvalidLeadSearch, MaxLeadSearchLimit)
+	}
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset cannot be negative", types.ErrInvalidLeadSearch)
+	}
+
+	return s.search.Search(ctx, req)
+}
@@ -2,12 +2,21 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/attachments"
 	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
 	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/query"
 
 	"github.com/google/uuid"
 )
@@ -17,12 +26,50 @@ type AssignmentRuleAssigner interface {
 	AssignLead(ctx context.Context, leadID uuid.UUID, conditions map[string]interface{}) (*types.AssignmentResult, error)
 }
 
+// ComputedFieldSource loads an organization's computed fields for an entity
+type ComputedFieldSource interface {
+	Fields(ctx context.Context, orgID uuid.UUID, entity string) ([]computed.Field, error)
+}
+
+// CustomFieldSource loads an organization's custom field definitions for an entity
+type CustomFieldSource interface {
+	Definitions(ctx context.Context, orgID uuid.UUID, entity string) ([]customfields.Definition, error)
+}
+
 // LeadService provides lead management functionality
 type LeadService struct {
 	repo                   types.LeadRepository
 	authService            auth.LegacyAuthService
 	eventBus               *events.Bus
 	assignmentRuleAssigner AssignmentRuleAssigner
+	computedFields         ComputedFieldSource
+	customFields           CustomFieldSource
+	stageHistory           types.LeadStageHistoryRepository
+	stageRepo              types.LeadStageRepository
+	conversion             types.LeadConversionRepository
+	outcomes               types.LeadOutcomeRepository
+	search                 types.LeadSearchRepository
+	board                  types.LeadBoardRepository
+	sla                    types.LeadSLARepository
+	bulk                   types.LeadBulkRepository
+	cadences               types.LeadCadenceRepository
+	sources                types.LeadSourceRepository
+	calendars              BusinessCalendarResolver
+	audit                  types.LeadAuditRepository
+	rotting                types.LeadRottingRepository
+	reassign               types.LeadReassignRepository
+	pipelineSnapshots      types.LeadPipelineSnapshotRepository
+	trash                  types.LeadTrashRepository
+	assignmentLoads        types.AssignmentEngineRepository
+	teams                  types.SalesTeamRepository
+	attachments            *attachments.Service
+	notifier               push.Notifier
+	entitlements           entitlements.Checker
+	periods                FiscalPeriods
+	logger                 *slog.Logger
+	// skipListTotals leaves the total out of lead pages, sparing the count
+	// over tables too large to count on every page
+	skipListTotals bool
 }
 
 // NewLeadService creates a new LeadService instance
@@ -32,6 +79,7 @@ func NewLeadService(repo types.LeadRepository, authService auth.LegacyAuthServic
 		authService:            authService,
 		eventBus:               eventBus,
 		assignmentRuleAssigner: assignmentRuleAssigner,
+		logger:                 slog.Default().With("service", "lead"),
 	}
 }
 
@@ -49,9 +97,19 @@ func (s *LeadService) CreateLead(ctx context.Context, orgID uuid.UUID, req types
 	if req.Priority == "" {
 		req.Priority = types.LeadPriorityMedium
 	}
+	// A probability given at creation is set by hand; otherwise the lead
+	// starts from its stage's probability, if any
+	manualProbability := req.Probability != 0
 	if req.Probability == 0 {
 		req.Probability = 10
 	}
+	status := types.LeadStatusNew
+	if req.Status != nil {
+		if !req.Status.IsValid() {
+			return types.Lead{}, fmt.Errorf("invalid lead status: %s", *req.Status)
+		}
+		status = *req.Status
+	}
 
 	// Create the lead entity
 	lead := types.Lead{
@@ -80,7 +138,7 @@ func (s *LeadService) CreateLead(ctx context.Context, orgID uuid.UUID, req types
 		DateClosed:       req.DateClosed,
 		DateDeadline:     req.DateDeadline,
 		Active:           req.Active,
-		Status:           req.Status,
+		Status:           status,
 		AssignedTo:       req.AssignedTo,
 		WonStatus:        req.WonStatus,
 		LostReasonID:     req.LostReasonID,
@@ -99,6 +157,33 @@ func (s *LeadService) CreateLead(ctx context.Context, orgID uuid.UUID, req types
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
+	if err := checkLostReason(lead); err != nil {
+		return types.Lead{}, err
+	}
+	customFields, err := s.checkCustomFields(ctx, orgID, lead.CustomFields)
+	if err != nil {
+		return types.Lead{}, err
+	}
+	lead.CustomFields = customFields
+	if manualProbability {
+		lead.OverrideProbability(req.Probability)
+	} else if lead.StageID != nil && s.stageRepo != nil {
+		stage, err := s.stageRepo.FindByID(ctx, *lead.StageID)
+		if err != nil {
+			return types.Lead{}, err
+		}
+		if stage.OrganizationID != orgID {
+			return types.Lead{}, errors.New("lead stage not found or access denied")
+		}
+		lead.ApplyStageProbability(stage.Probability)
+	}
+
+	// The organization's plan caps its leads
+	if s.entitlements != nil {
+		if err := s.entitlements.CheckLimit(ctx, orgID, entitlements.LimitLeads, 1); err != nil {
+			return types.Lead{}, err
+		}
+	}
 
 	// Apply assignment rules if available
 	if s.assignmentRuleAssigner != nil {
@@ -121,6 +206,10 @@ func (s *LeadService) CreateLead(ctx context.Context, orgID uuid.UUID, req types
 		return types.Lead{}, err
 	}
 
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.created", *createdLead)
+	}
+
 	return *createdLead, nil
 }
 
@@ -139,7 +228,9 @@ func (s *LeadService) GetLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID
 	return *lead, nil
 }
 
-// UpdateLead updates an existing lead
+// UpdateLead updates an existing lead. With a version, the update is
+// refused when the lead is at another one; without, it is still refused
+// when the lead is written between being read and updated.
 func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadUpdateRequest) (types.Lead, error) {
 	// Get the existing lead
 	existingLead, err := s.repo.FindByID(ctx, id)
@@ -151,6 +242,10 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 	if existingLead.OrganizationID != orgID {
 		return types.Lead{}, errors.New("lead not found or access denied")
 	}
+	if req.Version != nil && *req.Version != existingLead.Version {
+		return types.Lead{}, &types.LeadVersionConflictError{Current: existingLead}
+	}
+	before := *existingLead
 
 	// Apply updates
 	if req.Name != nil {
@@ -180,7 +275,7 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 	if req.LeadType != nil {
 		existingLead.LeadType = *req.LeadType
 	}
-	if req.StageID != nil {
+	if req.StageID != nil && s.stageHistory == nil {
 		existingLead.StageID = req.StageID
 	}
 	if req.Priority != nil {
@@ -199,7 +294,13 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 		existingLead.ExpectedRevenue = req.ExpectedRevenue
 	}
 	if req.Probability != nil {
-		existingLead.Probability = *req.Probability
+		existingLead.OverrideProbability(*req.Probability)
+	} else if req.ProbabilityOverridden != nil {
+		if *req.ProbabilityOverridden {
+			existingLead.OverrideProbability(existingLead.Probability)
+		} else {
+			existingLead.ResetProbability()
+		}
 	}
 	if req.RecurringRevenue != nil {
 		existingLead.RecurringRevenue = req.RecurringRevenue
@@ -220,7 +321,10 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 		existingLead.Active = *req.Active
 	}
 	if req.Status != nil {
-		existingLead.Status = req.Status
+		if !req.Status.IsValid() {
+			return types.Lead{}, fmt.Errorf("invalid lead status: %s", *req.Status)
+		}
+		existingLead.Status = *req.Status
 	}
 	if req.AssignedTo != nil {
 		existingLead.AssignedTo = req.AssignedTo
@@ -262,12 +366,35 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 		existingLead.Color = req.Color
 	}
 	if req.CustomFields != nil {
-		existingLead.CustomFields = req.CustomFields
+		customFields, err := s.checkCustomFields(ctx, orgID, req.CustomFields)
+		if err != nil {
+			return types.Lead{}, err
+		}
+		existingLead.CustomFields = customFields
 	}
 	if req.Metadata != nil {
 		existingLead.Metadata = req.Metadata
 	}
 
+	if err := checkLostReason(*existingLead); err != nil {
+		return types.Lead{}, err
+	}
+
+	// Stage changes go through the pipeline so they are checked and recorded.
+	// The move writes the lead, so it only happens once the rest of the
+	// update is known to be valid.
+	if req.StageID != nil && s.stageHistory != nil && (existingLead.StageID == nil || *existingLead.StageID != *req.StageID) {
+		change, stage, err := s.moveStage(ctx, *existingLead, *req.StageID, nil)
+		if err != nil {
+			return types.Lead{}, err
+		}
+		existingLead.StageID = req.StageID
+		existingLead.ApplyStageProbability(stage.Probability)
+		existingLead.DateLastStageUpdate = &change.ChangedAt
+		// The stage move wrote the lead once already
+		existingLead.Version++
+	}
+
 	existingLead.UpdatedAt = time.Now()
 
 	// Update the lead in the repository
@@ -276,6 +403,15 @@ func (s *LeadService) UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 		return types.Lead{}, err
 	}
 
+	// The update is committed; a lost history entry must not report it failed
+	if err := s.recordLeadChanges(ctx, before, *existingLead); err != nil {
+		s.logger.Error("Failed to record lead changes", "lead_id", updatedLead.ID, "error", err)
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.updated", *updatedLead)
+	}
+
 	return *updatedLead, nil
 }
 
@@ -292,17 +428,183 @@ func (s *LeadService) DeleteLead(ctx context.Context, orgID uuid.UUID, id uuid.U
 		return errors.New("lead not found or access denied")
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.deleted", map[string]interface{}{
+			"id":              id,
+			"organization_id": orgID,
+		})
+	}
+
+	return nil
+}
+
+// SetComputedFields makes lead lists include the organization's computed
+// lead fields and allows filtering on them
+func (s *LeadService) SetComputedFields(source ComputedFieldSource) {
+	s.computedFields = source
+}
+
+// SetEntitlements makes lead creation check the organization's plan
+func (s *LeadService) SetEntitlements(checker entitlements.Checker) {
+	s.entitlements = checker
+}
+
+// SetCustomFields makes lead writes check custom_fields against the
+// organization's custom field definitions and allows filtering on them
+func (s *LeadService) SetCustomFields(source CustomFieldSource) {
+	s.customFields = source
+}
+
+// checkCustomFields validates and normalizes a lead's custom field values.
+// Organizations without lead custom fields store any values as given.
+func (s *LeadService) checkCustomFields(ctx context.Context, orgID uuid.UUID, values interface{}) (interface{}, error) {
+	if s.customFields == nil {
+		return values, nil
+	}
+	defs, err := s.customFields.Definitions(ctx, orgID, "lead")
+	if err != nil {
+		return nil, err
+	}
+	return customfields.Values(defs, values)
+}
+
+// applyCustomFieldFilters compiles the filter's custom field filters to the
+// JSON document matching leads contain
+func (s *LeadService) applyCustomFieldFilters(ctx context.Context, filter *types.LeadFilter) error {
+	if len(filter.CustomFieldFilters) == 0 {
+		return nil
+	}
+	if s.customFields == nil {
+		return fmt.Errorf("%w: custom fields are not available", customfields.ErrUnknownField)
+	}
+
+	defs, err := s.customFields.Definitions(ctx, filter.OrganizationID, "lead")
+	if err != nil {
+		return err
+	}
+	doc, err := customfields.Contains(defs, filter.CustomFieldFilters)
+	if err != nil {
+		return err
+	}
+	contain, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode custom field filters: %w", err)
+	}
+	filter.CustomFieldsContain = string(contain)
+	return nil
+}
+
+// applyQuery compiles the filter's query against the lead's fields and
+// custom field definitions
+func (s *LeadService) applyQuery(ctx context.Context, filter *types.LeadFilter) error {
+	if filter.Query.IsEmpty() {
+		return nil
+	}
+
+	var defs []customfields.Definition
+	if s.customFields != nil {
+		var err error
+		if defs, err = s.customFields.Definitions(ctx, filter.OrganizationID, "lead"); err != nil {
+			return err
+		}
+	}
+	condition, err := query.Compile(*filter.Query, types.LeadQuerySchema, defs)
+	if err != nil {
+		return err
+	}
+	filter.Condition = condition
+	return nil
 }
 
 // ListLeads lists leads with filtering
 func (s *LeadService) ListLeads(ctx context.Context, orgID uuid.UUID, filter types.LeadFilter) ([]*types.Lead, error) {
 	filter.OrganizationID = orgID
+	if err := filter.ValidateSort(); err != nil {
+		return nil, err
+	}
+	if err := s.applyComputedFields(ctx, &filter, true); err != nil {
+		return nil, err
+	}
+	if err := s.applyCustomFieldFilters(ctx, &filter); err != nil {
+		return nil, err
+	}
+	if err := s.applyQuery(ctx, &filter); err != nil {
+		return nil, err
+	}
 	return s.repo.FindAll(ctx, filter)
 }
 
+// SetListTotals sets whether lead pages carry the total of matching leads.
+// Totals are on until turned off.
+func (s *LeadService) SetListTotals(enabled bool) {
+	s.skipListTotals = !enabled
+}
+
+// ListLeadsPage returns a page of leads with the total of all matching
+// leads, read in the same query, unless list totals are off
+func (s *LeadService) ListLeadsPage(ctx context.Context, orgID uuid.UUID, filter types.LeadFilter) (*types.LeadPage, error) {
+	filter.OrganizationID = orgID
+	if err := filter.ValidateSort(); err != nil {
+		return nil, err
+	}
+	if err := s.applyComputedFields(ctx, &filter, true); err != nil {
+		return nil, err
+	}
+	if err := s.applyCustomFieldFilters(ctx, &filter); err != nil {
+		return nil, err
+	}
+	if err := s.applyQuery(ctx, &filter); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPage(ctx, filter, !s.skipListTotals)
+}
+
 // CountLeads counts leads with filtering
 func (s *LeadService) CountLeads(ctx context.Context, orgID uuid.UUID, filter types.LeadFilter) (int, error) {
 	filter.OrganizationID = orgID
+	if err := s.applyComputedFields(ctx, &filter, false); err != nil {
+		return 0, err
+	}
+	if err := s.applyCustomFieldFilters(ctx, &filter); err != nil {
+		return 0, err
+	}
+	if err := s.applyQuery(ctx, &filter); err != nil {
+		return 0, err
+	}
 	return s.repo.Count(ctx, filter)
 }
+
+// applyComputedFields loads the computed lead fields into the filter and
+// checks that computed filters name defined fields. Fields are only loaded
+// when they are selected or filtered on.
+func (s *LeadService) applyComputedFields(ctx context.Context, filter *types.LeadFilter, selected bool) error {
+	filtered := len(filter.ComputedMin) > 0 || len(filter.ComputedMax) > 0
+	if s.computedFields == nil {
+		if filtered {
+			return fmt.Errorf("%w: computed fields are not available", computed.ErrUnknownField)
+		}
+		return nil
+	}
+	if !selected && !filtered {
+		return nil
+	}
+
+	fields, err := s.computedFields.Fields(ctx, filter.OrganizationID, "lead")
+	if err != nil {
+		return fmt.Errorf("failed to load computed fields: %w", err)
+	}
+	for _, bounds := range []map[string]float64{filter.ComputedMin, filter.ComputedMax} {
+		for name := range bounds {
+			if _, ok := computed.Lookup(fields, name); !ok {
+				return fmt.Errorf("%w: %s", computed.ErrUnknownField, name)
+			}
+		}
+	}
+
+	filter.ComputedFields = fields
+	return nil
+}
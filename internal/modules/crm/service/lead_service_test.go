@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func (s *leadStore) FindAll(_ context.Context, filter types.LeadFilter) ([]*types.Lead, error) {
+	s.filters = append(s.filters, filter)
+	return []*types.Lead{}, nil
+}
+
+func TestListLeadsInvalidSort(t *testing.T) {
+	leads := &leadStore{}
+	orgID := uuid.New()
+	svc := NewLeadService(leads, allowAuth{orgID: orgID}, nil, nil)
+	ctx := context.Background()
+
+	for _, filter := range []types.LeadFilter{
+		{SortBy: "name"},
+		{SortBy: types.LeadSortCreatedAt, SortDir: "sideways"},
+		{SortDir: types.SortDesc},
+	} {
+		listed, err := svc.ListLeads(ctx, orgID, filter)
+		require.ErrorIs(t, err, types.ErrInvalidLeadSort)
+		assert.Nil(t, listed)
+
+		page, err := svc.ListLeadsPage(ctx, orgID, filter)
+		require.ErrorIs(t, err, types.ErrInvalidLeadSort)
+		assert.Nil(t, page)
+	}
+	assert.Empty(t, leads.filters, "an invalid sort never reaches the repository")
+
+	_, err := svc.ListLeads(ctx, orgID, types.LeadFilter{SortBy: types.LeadSortCreatedAt, SortDir: types.SortDesc})
+	require.NoError(t, err)
+	require.Len(t, leads.filters, 1)
+	assert.Equal(t, types.LeadSortCreatedAt, leads.filters[0].SortBy)
+	assert.Equal(t, orgID, leads.filters[0].OrganizationID)
+}
+
+// failingAudit refuses every history entry
+type failingAudit struct {
+	types.LeadAuditRepository
+}
+
+func (failingAudit) Record(context.Context, []types.LeadAuditEntry) error {
+	return errors.New("audit table unavailable")
+}
+
+func TestUpdateLeadReturnsCommittedLeadWhenHistoryFails(t *testing.T) {
+	orgID := uuid.New()
+	leads := &leadStore{existing: &types.Lead{ID: uuid.New(), OrganizationID: orgID, Name: "Before"}}
+	svc := NewLeadService(leads, allowAuth{orgID: orgID}, nil, nil)
+	svc.SetAudit(failingAudit{})
+
+	name := "After"
+	updated, err := svc.UpdateLead(context.Background(), orgID, leads.existing.ID, types.LeadUpdateRequest{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "After", updated.Name)
+	require.Len(t, leads.written, 1)
+}
+
+// recordingStageHistory counts stage moves; everything else panics through
+// the nil embedded interface
+type recordingStageHistory struct {
+	types.LeadStageHistoryRepository
+	moves int
+}
+
+func (h *recordingStageHistory) FindTransitions(context.Context, uuid.UUID, *uuid.UUID) (*types.LeadStageTransitions, error) {
+	return &types.LeadStageTransitions{}, nil
+}
+
+func (h *recordingStageHistory) MoveStage(_ context.Context, change types.LeadStageChange, _ *int) (*types.LeadStageChange, error) {
+	h.moves++
+	return &change, nil
+}
+
+// orgStages finds any stage in the organization
+type orgStages struct {
+	types.LeadStageRepository
+	orgID uuid.UUID
+}
+
+func (s orgStages) FindByID(_ context.Context, id uuid.UUID) (*types.LeadStage, error) {
+	return &types.LeadStage{ID: id, OrganizationID: s.orgID}, nil
+}
+
+func TestUpdateLeadValidatesBeforeMovingStage(t *testing.T) {
+	orgID := uuid.New()
+	stageID := uuid.New()
+	invalidStatus := types.LeadStatus("sideways")
+	lost := types.LeadStatusLost
+
+	for name, req := range map[string]types.LeadUpdateRequest{
+		"invalid status":      {StageID: &stageID, Status: &invalidStatus},
+		"lost without reason": {StageID: &stageID, Status: &lost},
+	} {
+		t.Run(name, func(t *testing.T) {
+			leads := &leadStore{existing: &types.Lead{ID: uuid.New(), OrganizationID: orgID}}
+			history := &recordingStageHistory{}
+			svc := NewLeadService(leads, allowAuth{orgID: orgID}, nil, nil)
+			svc.SetStageHistory(history, orgStages{orgID: orgID})
+
+			_, err := svc.UpdateLead(context.Background(), orgID, leads.existing.ID, req)
+			require.Error(t, err)
+			assert.Zero(t, history.moves, "a refused update never moves the lead")
+			assert.Empty(t, leads.written)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LeadSharePath is the public view of a share link, followed by its token
+	LeadSharePath = "/public/v1/lead-views/"
+	// DefaultLeadShareLifetime is how long a link works when no expiry is given
+	DefaultLeadShareLifetime = 7 * 24 * time.Hour
+	// MaxLeadShareLifetime bounds how far ahead a link may expire
+	MaxLeadShareLifetime = 90 * 24 * time.Hour
+	// maxLeadShareFields bounds the fields a link shows
+	maxLeadShareFields = 50
+	// defaultLeadSharePageSize and maxLeadSharePageSize bound the leads
+	// shown per page of a public view
+	defaultLeadSharePageSize = 50
+	maxLeadSharePageSize     = 100
+)
+
+// LeadShareService shares filtered lead views with people without a login
+// through expiring read-only links, which show only the fields chosen for
+// them
+type LeadShareService struct {
+	repo        types.LeadShareRepository
+	leadService *LeadService
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewLeadShareService(repo types.LeadShareRepository, leadService *LeadService, authService auth.LegacyAuthService, eventBus *events.Bus) *LeadShareService {
+	return &LeadShareService{
+		repo:        repo,
+		leadService: leadService,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "lead-share"),
+		now:         time.Now,
+	}
+}
+
+// ListLinks lists the organization's share links, newest first
+func (s *LeadShareService) ListLinks(ctx context.Context, orgID uuid.UUID) ([]types.LeadShareLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_share_links:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	return s.repo.ListShareLinks(ctx, orgID)
+}
+
+// CreateLink shares a lead view. The link's path is only returned here.
+func (s *LeadShareService) CreateLink(ctx context.Context, orgID uuid.UUID, req types.LeadShareLinkRequest) (*types.LeadShareLink, error) {
+	// Sharing leads needs the right to read them
+	for _, permission := range []string{"crm:lead_share_links:create", "crm:leads:read"} {
+		if err := s.authService.CheckPermission(ctx, permission); err != nil {
+			return nil, fmt.Errorf("permission denied: %w", err)
+		}
+	}
+
+	now := s.now()
+	link := types.LeadShareLink{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Query:          req.Query,
+		SortBy:         types.LeadSortField(strings.ToLower(req.SortBy)),
+		SortDir:        types.SortDirection(strings.ToLower(req.SortDir)),
+		ExpiresAt:      now.Add(DefaultLeadShareLifetime),
+		CreatedAt:      now,
+	}
+	if link.Name == "" || len(link.Name) > 255 {
+		return nil, fmt.Errorf("%w: name is required and at most 255 characters", types.ErrInvalidLeadShareLink)
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) || req.ExpiresAt.After(now.Add(MaxLeadShareLifetime)) {
+			return nil, fmt.Errorf("%w: links must expire within %d days", types.ErrInvalidLeadShareLink, int(MaxLeadShareLifetime.Hours()/24))
+		}
+		link.ExpiresAt = *req.ExpiresAt
+	}
+	if link.Query.IsEmpty() {
+		link.Query = nil
+	}
+
+	// The view is checked as it will be listed
+	filter := types.LeadFilter{OrganizationID: orgID, Query: link.Query, SortBy: link.SortBy, SortDir: link.SortDir}
+	if err := filter.ValidateSort(); err != nil {
+		return nil, err
+	}
+	if err := s.leadService.applyQuery(ctx, &filter); err != nil {
+		return nil, err
+	}
+	fields, err := s.shareFields(ctx, orgID, req.Fields)
+	if err != nil {
+		return nil, err
+	}
+	link.Fields = fields
+
+	token, err := newConsentToken()
+	if err != nil {
+		return nil, err
+	}
+	link.TokenHash = hashConsentToken(token)
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		link.CreatedBy = &userID
+	}
+
+	created, err := s.repo.CreateShareLink(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	created.SharePath = LeadSharePath + token
+
+	s.logger.Info("Created lead share link", "link_id", created.ID, "expires_at", created.ExpiresAt)
+	s.publishEvent(ctx, "lead.share_link_created", created)
+	return created, nil
+}
+
+// shareFields checks the fields a link shows against the shareable lead
+// fields and the organization's custom fields, defaulting to
+// DefaultLeadShareFields
+func (s *LeadShareService) shareFields(ctx context.Context, orgID uuid.UUID, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return append([]string(nil), types.DefaultLeadShareFields...), nil
+	}
+	if len(fields) > maxLeadShareFields {
+		return nil, fmt.Errorf("%w: at most %d fields can be shown", types.ErrInvalidLeadShareLink, maxLeadShareFields)
+	}
+
+	var defs []customfields.Definition
+	shown := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+
+		if name, ok := strings.CutPrefix(field, query.CustomFieldPrefix); ok {
+			if defs == nil && s.leadService.customFields != nil {
+				var err error
+				if defs, err = s.leadService.customFields.Definitions(ctx, orgID, "lead"); err != nil {
+					return nil, err
+				}
+			}
+			if _, ok := customfields.Lookup(defs, name); !ok {
+				return nil, fmt.Errorf("%w: unknown custom field %q", types.ErrInvalidLeadShareLink, name)
+			}
+		} else if !types.LeadShareFields[field] {
+			return nil, fmt.Errorf("%w: field %q cannot be shared", types.ErrInvalidLeadShareLink, field)
+		}
+		shown = append(shown, field)
+	}
+	return shown, nil
+}
+
+// RevokeLink ends a share link before it expires
+func (s *LeadShareService) RevokeLink(ctx context.Context, orgID, id uuid.UUID) (*types.LeadShareLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_share_links:delete"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	var revokedBy *uuid.UUID
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		revokedBy = &userID
+	}
+	link, err := s.repo.RevokeShareLink(ctx, orgID, id, revokedBy, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Revoked lead share link", "link_id", link.ID)
+	s.publishEvent(ctx, "lead.share_link_revoked", link)
+	return link, nil
+}
+
+// ViewLeads returns a page of the leads of a live share link, with only
+// the link's fields of each lead. limit defaults to 50, and is at most 100.
+func (s *LeadShareService) ViewLeads(ctx context.Context, token string, limit, offset int) (*types.PublicLeadView, error) {
+	if limit <= 0 {
+		limit = defaultLeadSharePageSize
+	}
+	if limit > maxLeadSharePageSize {
+		limit = maxLeadSharePageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	link, err := s.repo.ViewShareLink(ctx, hashConsentToken(token), s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	filter := types.LeadFilter{Query: link.Query, SortBy: link.SortBy, SortDir: link.SortDir, Limit: limit, Offset: offset}
+	page, err := s.leadService.ListLeadsPage(ctx, link.OrganizationID, filter)
+	if err != nil {
+		return nil, err
+	}
+	// The page is counted in the same query unless list totals are off
+	var total int
+	if page.Total != nil {
+		total = *page.Total
+	} else if total, err = s.leadService.CountLeads(ctx, link.OrganizationID, filter); err != nil {
+		return nil, err
+	}
+
+	view := &types.PublicLeadView{
+		Name:      link.Name,
+		Fields:    link.Fields,
+		ExpiresAt: link.ExpiresAt,
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+		Data:      make([]map[string]interface{}, 0, len(page.Data)),
+	}
+	for _, lead := range page.Data {
+		row, err := redactLead(lead, link.Fields)
+		if err != nil {
+			return nil, err
+		}
+		view.Data = append(view.Data, row)
+	}
+	return view, nil
+}
+
+// redactLead keeps only the given fields of a lead, set to null when the
+// lead has no value for them
+func redactLead(lead *types.Lead, fields []string) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(lead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lead: %w", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode lead: %w", err)
+	}
+
+	// Custom field values are read as stored JSON or as set on the lead
+	custom := map[string]interface{}{}
+	switch v := lead.CustomFields.(type) {
+	case []byte:
+		if len(v) > 0 {
+			if err := json.Unmarshal(v, &custom); err != nil {
+				return nil, fmt.Errorf("failed to decode lead custom fields: %w", err)
+			}
+		}
+	case map[string]interface{}:
+		custom = v
+	}
+
+	row := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if name, ok := strings.CutPrefix(field, query.CustomFieldPrefix); ok {
+			row[field] = custom[name]
+		} else {
+			row[field] = values[field]
+		}
+	}
+	return row, nil
+}
+
+func (s *LeadShareService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish lead share event", "event", eventType, "error", err)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// LeadSLAInterval is how often SLA clocks are started, stopped and
+	// checked for breaches
+	LeadSLAInterval = time.Minute
+	// leadSLABatchSize bounds the clocks started and escalated per pass
+	leadSLABatchSize = 500
+	// defaultSLABreachLimit and maxSLABreachLimit bound a page of breaches
+	defaultSLABreachLimit = 50
+	maxSLABreachLimit     = 500
+)
+
+// slaEscalationConditions selects the assignment rules that take over
+// breached leads
+var slaEscalationConditions = map[string]interface{}{"sla_breach": true}
+
+// SetSLA enables lead response-time targets. Targets are counted in the
+// business hours of each lead's team; without calendars they run around the
+// clock.
+func (s *LeadService) SetSLA(sla types.LeadSLARepository, calendars BusinessCalendarResolver) {
+	s.sla = sla
+	s.calendars = calendars
+}
+
+// ListSLAPolicies lists the organization's SLA policies, most specific first
+func (s *LeadService) ListSLAPolicies(ctx context.Context, orgID uuid.UUID) ([]types.LeadSLAPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_sla_policies:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	return s.sla.ListPolicies(ctx, orgID)
+}
+
+// CreateSLAPolicy adds a response-time target for leads of a stage and/or
+// priority
+func (s *LeadService) CreateSLAPolicy(ctx context.Context, orgID uuid.UUID, req types.LeadSLAPolicyRequest) (*types.LeadSLAPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_sla_policies:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	now := time.Now()
+	policy := types.LeadSLAPolicy{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Escalate:       true,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.applySLAPolicyRequest(ctx, &policy, req); err != nil {
+		return nil, err
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		policy.CreatedBy = &userID
+		policy.UpdatedBy = &userID
+	}
+
+	return s.sla.CreatePolicy(ctx, policy)
+}
+
+// UpdateSLAPolicy replaces an SLA policy's target. Clocks already running
+// keep the due time they started with.
+func (s *LeadService) UpdateSLAPolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadSLAPolicyRequest) (*types.LeadSLAPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_sla_policies:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	policy, err := s.sla.FindPolicy(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySLAPolicyRequest(ctx, policy, req); err != nil {
+		return nil, err
+	}
+	policy.UpdatedAt = time.Now()
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		policy.UpdatedBy = &userID
+	}
+
+	return s.sla.UpdatePolicy(ctx, *policy)
+}
+
+// DeleteSLAPolicy removes an SLA policy. Its clocks remain in the compliance
+// figures but no longer escalate.
+func (s *LeadService) DeleteSLAPolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_sla_policies:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return errors.New("lead SLAs are not available")
+	}
+
+	return s.sla.DeletePolicy(ctx, orgID, id)
+}
+
+func (s *LeadService) applySLAPolicyRequest(ctx context.Context, policy *types.LeadSLAPolicy, req types.LeadSLAPolicyRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrInvalidLeadSLAPolicy)
+	}
+	if req.ResponseMinutes <= 0 {
+		return fmt.Errorf("%w: response_minutes must be positive", types.ErrInvalidLeadSLAPolicy)
+	}
+	if req.Priority != nil && !req.Priority.IsValid() {
+		return fmt.Errorf("%w: unknown priority %q", types.ErrInvalidLeadSLAPolicy, *req.Priority)
+	}
+	if req.StageID != nil && s.stageRepo != nil {
+		stage, err := s.stageRepo.FindByID(ctx, *req.StageID)
+		if err != nil {
+			return err
+		}
+		if stage.OrganizationID != policy.OrganizationID {
+			return errors.New("lead stage not found or access denied")
+		}
+	}
+
+	policy.Name = name
+	policy.StageID = req.StageID
+	policy.Priority = req.Priority
+	policy.ResponseMinutes = req.ResponseMinutes
+	if req.Escalate != nil {
+		policy.Escalate = *req.Escalate
+	}
+	if req.Active != nil {
+		policy.Active = *req.Active
+	}
+	return nil
+}
+
+// GetSLABreaches lists the leads that missed their response target, latest
+// breach first
+func (s *LeadService) GetSLABreaches(ctx context.Context, orgID uuid.UUID, filter types.LeadSLABreachFilter) ([]types.LeadSLAClock, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		return nil, errors.New("date_to must be after date_from")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSLABreachLimit
+	}
+	if filter.Limit > maxSLABreachLimit {
+		filter.Limit = maxSLABreachLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	filter.OrganizationID = orgID
+	return s.sla.Breaches(ctx, filter)
+}
+
+// GetSLACompliance reports, per sales team, how many response targets were
+// met and missed
+func (s *LeadService) GetSLACompliance(ctx context.Context, orgID uuid.UUID, filter types.LeadSLAComplianceFilter) ([]types.LeadSLACompliance, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		return nil, errors.New("date_to must be after date_from")
+	}
+
+	filter.OrganizationID = orgID
+	return s.sla.Compliance(ctx, filter)
+}
+
+// EnforceSLAs starts clocks for leads entering a stage with a target, stops
+// the clocks of leads that were responded to, flags the ones past due and
+// escalates breaches through the assignment rules. It is invoked by the
+// scheduled job and therefore bypasses the per-request permission check.
+// Failures of single leads do not stop the pass and are returned together.
+func (s *LeadService) EnforceSLAs(ctx context.Context, now time.Time) (*types.LeadSLARun, error) {
+	if s.sla == nil {
+		return nil, errors.New("lead SLAs are not available")
+	}
+
+	var run types.LeadSLARun
+	var errs []error
+
+	entries, err := s.sla.UntimedEntries(ctx, leadSLABatchSize)
+	if err != nil {
+		return nil, err
+	}
+	calendars := make(map[string]*calendar.Calendar)
+	for _, entry := range entries {
+		cal, err := s.slaCalendar(ctx, calendars, entry.OrganizationID, entry.TeamID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lead %s: %w", entry.LeadID, err))
+			continue
+		}
+		policyID := entry.PolicyID
+		clock := types.LeadSLAClock{
+			ID:             uuid.New(),
+			OrganizationID: entry.OrganizationID,
+			LeadID:         entry.LeadID,
+			PolicyID:       &policyID,
+			TeamID:         entry.TeamID,
+			StageID:        entry.StageID,
+			Priority:       entry.Priority,
+			AssignedTo:     entry.AssignedTo,
+			Escalate:       entry.Escalate,
+			StartedAt:      entry.EnteredAt,
+			DueAt:          cal.Add(entry.EnteredAt, time.Duration(entry.ResponseMinutes)*time.Minute),
+		}
+		if err := s.sla.StartClock(ctx, clock); err != nil {
+			errs = append(errs, fmt.Errorf("lead %s: %w", entry.LeadID, err))
+			continue
+		}
+		run.Started++
+	}
+
+	if run.Responded, err = s.sla.RecordResponses(ctx); err != nil {
+		return nil, err
+	}
+
+	breached, err := s.sla.FlagBreaches(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	run.Breached = len(breached)
+	if s.eventBus != nil {
+		for _, clock := range breached {
+			s.eventBus.Publish(ctx, "crm.lead.sla_breached", clock)
+		}
+	}
+
+	if s.assignmentRuleAssigner != nil {
+		pending, err := s.sla.PendingEscalations(ctx, leadSLABatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, clock := range pending {
+			escalated, err := s.escalateSLABreach(ctx, clock, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("lead %s: %w", clock.LeadID, err))
+			}
+			if escalated {
+				run.Escalated++
+			}
+		}
+	}
+
+	return &run, errors.Join(errs...)
+}
+
+// escalateSLABreach hands a breached lead to the assignee chosen by the
+// assignment rules matching sla_breach. Outside business hours the
+// escalation waits for the next pass; a lead no rule applies to is recorded
+// as not escalated.
+func (s *LeadService) escalateSLABreach(ctx context.Context, clock types.LeadSLAClock, now time.Time) (bool, error) {
+	result, err := s.assignmentRuleAssigner.AssignLead(ctx, clock.LeadID, slaEscalationConditions)
+	if err != nil {
+		if markErr := s.sla.MarkEscalated(ctx, clock.ID, nil, "escalation_failed", now); markErr != nil {
+			return false, markErr
+		}
+		return false, fmt.Errorf("failed to escalate SLA breach: %w", err)
+	}
+	if result.Reason == "outside_business_hours" {
+		return false, nil
+	}
+
+	// A lead the rules hand back to its current assignee is not escalated
+	var escalatedTo *uuid.UUID
+	if result.Changed {
+		escalatedTo = &result.AssignedToID
+	}
+	if err := s.sla.MarkEscalated(ctx, clock.ID, escalatedTo, result.Reason, now); err != nil {
+		return false, err
+	}
+	if !result.Changed {
+		return false, nil
+	}
+
+	escalatedAt := now
+	clock.EscalatedAt = &escalatedAt
+	clock.EscalatedTo = &result.AssignedToID
+	clock.EscalationResult = &result.Reason
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.sla_escalated", clock)
+	}
+	return true, nil
+}
+
+func (s *LeadService) slaCalendar(ctx context.Context, cache map[string]*calendar.Calendar, orgID uuid.UUID, teamID *uuid.UUID) (*calendar.Calendar, error) {
+	if s.calendars == nil {
+		return calendar.Always(), nil
+	}
+	key := orgID.String()
+	if teamID != nil {
+		key += "/" + teamID.String()
+	}
+	if cal, ok := cache[key]; ok {
+		return cal, nil
+	}
+	cal, err := s.calendars.Resolve(ctx, orgID, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve business calendar: %w", err)
+	}
+	cache[key] = cal
+	return cal, nil
+}
@@ -62,6 +62,7 @@ func (s *LeadStageService) CreateLeadStage(ctx context.Context, req types.LeadSt
 		IsWon:          req.IsWon,
 		Requirements:   req.Requirements,
 		TeamID:         req.TeamID,
+		RottingDays:    req.RottingDays,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -159,11 +160,12 @@ func (s *LeadStageService) UpdateLeadStage(ctx context.Context, id uuid.UUID, re
 		OrganizationID: orgID,
 		Name:           *req.Name,
 		Sequence:       *req.Sequence,
-		Probability:    *req.Probability,
+		Probability:    req.Probability,
 		Fold:           *req.Fold,
 		IsWon:          *req.IsWon,
 		Requirements:   req.Requirements,
 		TeamID:         req.TeamID,
+		RottingDays:    req.RottingDays,
 		UpdatedAt:      time.Now(),
 	}
 
@@ -226,7 +228,7 @@ func (s *LeadStageService) validateLeadStage(req types.LeadStageCreateRequest) e
 		return errors.New("name must be 100 characters or less")
 	}
 
-	if req.Probability < 0 || req.Probability > 100 {
+	if req.Probability != nil && (*req.Probability < 0 || *req.Probability > 100) {
 		return errors.New("probability must be between 0 and 100")
 	}
 
@@ -238,6 +240,10 @@ func (s *LeadStageService) validateLeadStage(req types.LeadStageCreateRequest) e
 		return errors.New("requirements must be 10000 characters or less")
 	}
 
+	if req.RottingDays != nil && *req.RottingDays <= 0 {
+		return errors.New("rotting_days must be positive")
+	}
+
 	return nil
 }
 
@@ -268,5 +274,9 @@ func (s *LeadStageService) validateLeadStageUpdate(req types.LeadStageUpdateRequ
 		return errors.New("requirements must be 10000 characters or less")
 	}
 
+	if req.RottingDays != nil && *req.RottingDays <= 0 {
+		return errors.New("rotting_days must be positive")
+	}
+
 	return nil
 }
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+// SetStageHistory records every stage change of a lead and enforces the
+// stage transitions configured for its pipeline
+func (s *LeadService) SetStageHistory(history types.LeadStageHistoryRepository, stages types.LeadStageRepository) {
+	s.stageHistory = history
+	s.stageRepo = stages
+}
+
+// MoveStage moves a lead to another stage of its pipeline
+func (s *LeadService) MoveStage(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadMoveStageRequest) (*types.LeadStageChange, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	lead, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify organization ownership
+	if lead.OrganizationID != orgID {
+		return nil, errors.New("lead not found or access denied")
+	}
+
+	change, _, err := s.moveStage(ctx, *lead, req.StageID, req.Note)
+	return change, err
+}
+
+// moveStage checks the move against the lead's pipeline and records it. It
+// returns the change and the stage the lead moved to.
+func (s *LeadService) moveStage(ctx context.Context, lead types.Lead, stageID uuid.UUID, note *string) (*types.LeadStageChange, *types.LeadStage, error) {
+	if s.stageHistory == nil || s.stageRepo == nil {
+		return nil, nil, errors.New("lead stage history is not available")
+	}
+
+	if lead.StageID != nil && *lead.StageID == stageID {
+		return nil, nil, fmt.Errorf("%w: lead is already in stage %s", types.ErrStageTransitionNotAllowed, stageID)
+	}
+
+	stage, err := s.stageRepo.FindByID(ctx, stageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stage.OrganizationID != lead.OrganizationID {
+		return nil, nil, errors.New("lead stage not found or access denied")
+	}
+	if stage.TeamID != nil && (lead.TeamID == nil || *lead.TeamID != *stage.TeamID) {
+		return nil, nil, fmt.Errorf("%w: stage %s belongs to another pipeline", types.ErrStageTransitionNotAllowed, stage.Name)
+	}
+
+	transitions, err := s.stageHistory.FindTransitions(ctx, lead.OrganizationID, lead.TeamID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get lead stage transitions: %w", err)
+	}
+	if !transitions.Allows(lead.StageID, stageID) {
+		return nil, nil, fmt.Errorf("%w: lead cannot move to stage %s from its current stage", types.ErrStageTransitionNotAllowed, stage.Name)
+	}
+
+	change := types.LeadStageChange{
+		ID:             uuid.New(),
+		OrganizationID: lead.OrganizationID,
+		LeadID:         lead.ID,
+		TeamID:         lead.TeamID,
+		FromStageID:    lead.StageID,
+		ToStageID:      &stageID,
+		ChangedAt:      time.Now(),
+		Note:           note,
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		change.ChangedBy = &userID
+	}
+
+	moved, err := s.stageHistory.MoveStage(ctx, change, stage.Probability)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.stage_changed", moved)
+	}
+
+	return moved, stage, nil
+}
+
+// GetStageHistory lists a lead's stage changes, oldest first
+func (s *LeadService) GetStageHistory(ctx context.Context, orgID uuid.UUID, id uuid.UUID) ([]types.LeadStageChange, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.stageHistory == nil {
+		return nil, errors.New("lead stage history is not available")
+	}
+
+	lead, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify organization ownership
+	if lead.OrganizationID != orgID {
+		return nil, errors.New("lead not found or access denied")
+	}
+
+	return s.stageHistory.FindByLead(ctx, orgID, id)
+}
+
+// GetStageVelocity reports how long leads spend in each stage before moving on
+func (s *LeadService) GetStageVelocity(ctx context.Context, orgID uuid.UUID, filter types.StageVelocityFilter) ([]types.StageVelocity, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.stageHistory == nil {
+		return nil, errors.New("lead stage history is not available")
+	}
+
+	if filter.DateFrom != nil && filter.DateTo != nil && !filter.DateTo.After(*filter.DateFrom) {
+		return nil, errors.New("date_to must be after date_from")
+	}
+
+	filter.OrganizationID = orgID
+	return s.stageHistory.Velocity(ctx, filter)
+}
+
+// GetStageTransitions returns the transitions that apply to a team's leads,
+// or the organization default when teamID is nil
+func (s *LeadService) GetStageTransitions(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*types.LeadStageTransitions, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_stages:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.stageHistory == nil {
+		return nil, errors.New("lead stage history is not available")
+	}
+
+	return s.stageHistory.FindTransitions(ctx, orgID, teamID)
+}
+
+// SetStageTransitions replaces the transitions of a pipeline. Every stage
+// must belong to the organization and to the pipeline's team, or to no team.
+func (s *LeadService) SetStageTransitions(ctx context.Context, orgID uuid.UUID, transitions types.LeadStageTransitions) (*types.LeadStageTransitions, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:lead_stages:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.stageHistory == nil || s.stageRepo == nil {
+		return nil, errors.New("lead stage history is not available")
+	}
+
+	checked := make(map[uuid.UUID]bool)
+	seen := make(map[types.LeadStageTransition]bool)
+	unique := make([]types.LeadStageTransition, 0, len(transitions.Transitions))
+	for _, transition := range transitions.Transitions {
+		if transition.FromStageID == transition.ToStageID {
+			return nil, fmt.Errorf("%w: a stage cannot transition to itself", types.ErrStageTransitionNotAllowed)
+		}
+		for _, stageID := range []uuid.UUID{transition.FromStageID, transition.ToStageID} {
+			if checked[stageID] {
+				continue
+			}
+			stage, err := s.stageRepo.FindByID(ctx, stageID)
+			if err != nil {
+				return nil, err
+			}
+			if stage.OrganizationID != orgID {
+				return nil, errors.New("lead stage not found or access denied")
+			}
+			if stage.TeamID != nil && (transitions.TeamID == nil || *stage.TeamID != *transitions.TeamID) {
+				return nil, fmt.Errorf("%w: stage %s belongs to another pipeline", types.ErrStageTransitionNotAllowed, stage.Name)
+			}
+			checked[stageID] = true
+		}
+		if !seen[transition] {
+			seen[transition] = true
+			unique = append(unique, transition)
+		}
+	}
+	transitions.Transitions = unique
+
+	if err := s.stageHistory.ReplaceTransitions(ctx, orgID, transitions); err != nil {
+		return nil, err
+	}
+
+	return &transitions, nil
+}
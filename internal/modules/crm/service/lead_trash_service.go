@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLeadTrashLimit is the page size of the trash when none is set
+	DefaultLeadTrashLimit = 50
+	// MaxLeadTrashLimit bounds the page size of the trash
+	MaxLeadTrashLimit = 200
+)
+
+// SetTrash enables listing and restoring soft-deleted leads
+func (s *LeadService) SetTrash(trash types.LeadTrashRepository) {
+	s.trash = trash
+}
+
+// ListDeletedLeads returns a page of the organization's soft-deleted leads,
+// the most recently deleted first. They stay in the trash until the
+// organization's deleted leads retention purges them.
+func (s *LeadService) ListDeletedLeads(ctx context.Context, orgID uuid.UUID, limit, offset int) (*types.LeadPage, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.trash == nil {
+		return nil, errors.New("lead trash is not available")
+	}
+
+	if limit <= 0 {
+		limit = DefaultLeadTrashLimit
+	}
+	limit = min(limit, MaxLeadTrashLimit)
+	offset = max(offset, 0)
+	return s.trash.ListDeleted(ctx, orgID, limit, offset)
+}
+
+// RestoreLead takes a lead out of the trash
+func (s *LeadService) RestoreLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.Lead, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:delete"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.trash == nil {
+		return nil, errors.New("lead trash is not available")
+	}
+
+	var restoredBy *uuid.UUID
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		restoredBy = &userID
+	}
+	lead, err := s.trash.Restore(ctx, orgID, id, restoredBy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.restored", map[string]interface{}{
+			"id":              id,
+			"organization_id": orgID,
+		})
+	}
+
+	return lead, nil
+}
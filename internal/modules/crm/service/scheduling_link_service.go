@@ -0,0 +1,542 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+// SchedulingLinkPath is the public booking page, followed by the link token
+const SchedulingLinkPath = "/public/v1/booking/"
+
+// maxSchedulingRange bounds the period a single slot listing covers
+const maxSchedulingRange = 31 * 24 * time.Hour
+
+// CalendarBusySource returns the busy time of users from their calendar events
+type CalendarBusySource interface {
+	Busy(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) (map[uuid.UUID][]calendar.Span, error)
+}
+
+// SchedulingLinkService manages the public booking pages of users and sales
+// teams and books the meetings prospects pick on them
+type SchedulingLinkService struct {
+	repo        types.SchedulingLinkRepository
+	leadRepo    types.LeadRepository
+	teamRepo    types.SalesTeamRepository
+	sourceRepo  types.LeadSourceRepository
+	leadService *LeadService
+	calendars   BusinessCalendarResolver
+	busy        CalendarBusySource
+	authService auth.LegacyAuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewSchedulingLinkService(repo types.SchedulingLinkRepository, leadRepo types.LeadRepository, teamRepo types.SalesTeamRepository, sourceRepo types.LeadSourceRepository, leadService *LeadService, authService auth.LegacyAuthService, eventBus *events.Bus) *SchedulingLinkService {
+	return &SchedulingLinkService{
+		repo:        repo,
+		leadRepo:    leadRepo,
+		teamRepo:    teamRepo,
+		sourceRepo:  sourceRepo,
+		leadService: leadService,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      slog.Default().With("service", "scheduling-link"),
+		now:         time.Now,
+	}
+}
+
+// SetCalendars makes links offer slots within business hours that the hosts'
+// calendar events leave free. Without calendars every time is offered.
+func (s *SchedulingLinkService) SetCalendars(calendars BusinessCalendarResolver, busy CalendarBusySource) {
+	s.calendars = calendars
+	s.busy = busy
+}
+
+// ListLinks lists the organization's scheduling links
+func (s *SchedulingLinkService) ListLinks(ctx context.Context, orgID uuid.UUID) ([]types.SchedulingLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:scheduling_links:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	links, err := s.repo.ListLinks(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		links[i].BookingPath = SchedulingLinkPath + links[i].Token
+	}
+	return links, nil
+}
+
+// GetLink returns a scheduling link by ID
+func (s *SchedulingLinkService) GetLink(ctx context.Context, orgID, id uuid.UUID) (*types.SchedulingLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:scheduling_links:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	link, err := s.repo.FindLink(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	link.BookingPath = SchedulingLinkPath + link.Token
+	return link, nil
+}
+
+// CreateLink adds a scheduling link with a new token
+func (s *SchedulingLinkService) CreateLink(ctx context.Context, orgID uuid.UUID, req types.SchedulingLinkRequest) (*types.SchedulingLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:scheduling_links:create"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	now := s.now()
+	link := types.SchedulingLink{
+		ID:              uuid.New(),
+		OrganizationID:  orgID,
+		DurationMinutes: 30,
+		HorizonDays:     30,
+		Active:          true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		link.CreatedBy = &userID
+		link.UpdatedBy = &userID
+	}
+	req.RotateToken = true
+	if err := s.applyLinkRequest(ctx, &link, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateLink(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	created.BookingPath = SchedulingLinkPath + created.Token
+
+	s.logger.Info("Created scheduling link", "link_id", created.ID)
+	return created, nil
+}
+
+// UpdateLink changes a scheduling link, issuing a new token when asked
+func (s *SchedulingLinkService) UpdateLink(ctx context.Context, orgID, id uuid.UUID, req types.SchedulingLinkRequest) (*types.SchedulingLink, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:scheduling_links:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	link, err := s.repo.FindLink(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	link.UpdatedAt = s.now()
+	if userID, err := s.authService.GetUserID(ctx); err == nil {
+		link.UpdatedBy = &userID
+	}
+	if err := s.applyLinkRequest(ctx, link, req); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateLink(ctx, *link)
+	if err != nil {
+		return nil, err
+	}
+	updated.BookingPath = SchedulingLinkPath + updated.Token
+	return updated, nil
+}
+
+// DeleteLink removes a scheduling link; the meetings booked through it stay
+// on their leads and calendars
+func (s *SchedulingLinkService) DeleteLink(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:scheduling_links:delete"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+
+	return s.repo.DeleteLink(ctx, orgID, id)
+}
+
+func (s *SchedulingLinkService) applyLinkRequest(ctx context.Context, link *types.SchedulingLink, req types.SchedulingLinkRequest) error {
+	if req.UserID != nil && req.TeamID != nil {
+		return fmt.Errorf("%w: a link is for either a user or a team", types.ErrInvalidSchedulingLink)
+	}
+	if req.Name != nil {
+		link.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.UserID != nil {
+		link.UserID, link.TeamID = req.UserID, nil
+	}
+	if req.TeamID != nil {
+		team, err := s.teamRepo.FindByID(ctx, *req.TeamID)
+		if err != nil || team.OrganizationID != link.OrganizationID || team.DeletedAt != nil {
+			return fmt.Errorf("%w: sales team not found", types.ErrInvalidSchedulingLink)
+		}
+		link.UserID, link.TeamID = nil, req.TeamID
+	}
+	if req.DurationMinutes != nil {
+		link.DurationMinutes = *req.DurationMinutes
+	}
+	if req.StepMinutes != nil {
+		link.StepMinutes = *req.StepMinutes
+	}
+	if link.StepMinutes == 0 {
+		link.StepMinutes = link.DurationMinutes
+	}
+	if req.BufferMinutes != nil {
+		link.BufferMinutes = *req.BufferMinutes
+	}
+	if req.MinNoticeMinutes != nil {
+		link.MinNoticeMinutes = *req.MinNoticeMinutes
+	}
+	if req.HorizonDays != nil {
+		link.HorizonDays = *req.HorizonDays
+	}
+	if req.SourceID != nil {
+		source, err := s.sourceRepo.FindByID(ctx, *req.SourceID)
+		if err != nil || source.OrganizationID != link.OrganizationID {
+			return fmt.Errorf("%w: lead source not found", types.ErrInvalidSchedulingLink)
+		}
+		link.SourceID = req.SourceID
+	}
+	if req.Active != nil {
+		link.Active = *req.Active
+	}
+	if req.RotateToken {
+		token, err := newSchedulingLinkToken()
+		if err != nil {
+			return err
+		}
+		link.Token = token
+	}
+
+	switch {
+	case link.Name == "":
+		return fmt.Errorf("%w: name is required", types.ErrInvalidSchedulingLink)
+	case len(link.Name) > 255:
+		return fmt.Errorf("%w: name must be 255 characters or less", types.ErrInvalidSchedulingLink)
+	case link.UserID == nil && link.TeamID == nil:
+		return fmt.Errorf("%w: user_id or team_id is required", types.ErrInvalidSchedulingLink)
+	case link.DurationMinutes < 5 || link.DurationMinutes > 8*60:
+		return fmt.Errorf("%w: duration must be between 5 minutes and 8 hours", types.ErrInvalidSchedulingLink)
+	case link.StepMinutes < 5 || link.StepMinutes > 24*60:
+		return fmt.Errorf("%w: step must be between 5 minutes and a day", types.ErrInvalidSchedulingLink)
+	case link.BufferMinutes < 0 || link.BufferMinutes > 4*60:
+		return fmt.Errorf("%w: buffer must be between 0 and 4 hours", types.ErrInvalidSchedulingLink)
+	case link.MinNoticeMinutes < 0:
+		return fmt.Errorf("%w: minimum notice cannot be negative", types.ErrInvalidSchedulingLink)
+	case link.HorizonDays < 1 || link.HorizonDays > 365:
+		return fmt.Errorf("%w: horizon must be between 1 and 365 days", types.ErrInvalidSchedulingLink)
+	}
+	return nil
+}
+
+func newSchedulingLinkToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate scheduling link token: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// ListLeadBookings returns the meetings booked by a lead, latest first
+func (s *SchedulingLinkService) ListLeadBookings(ctx context.Context, orgID, leadID uuid.UUID) ([]types.LeadBooking, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:leads:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.leadService.GetLead(ctx, orgID, leadID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListBookings(ctx, orgID, leadID)
+}
+
+// GetPublicLink returns what the booking page of a token shows
+func (s *SchedulingLinkService) GetPublicLink(ctx context.Context, token string) (*types.PublicSchedulingLink, error) {
+	link, err := s.repo.FindLinkByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.calendar(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PublicSchedulingLink{
+		Name:            link.Name,
+		DurationMinutes: link.DurationMinutes,
+		Timezone:        cal.Location().String(),
+	}, nil
+}
+
+// ListSlots returns the times in from..to at which a host of the link is
+// free, within the link's notice and horizon
+func (s *SchedulingLinkService) ListSlots(ctx context.Context, token string, from, to time.Time) ([]types.SchedulingSlot, error) {
+	if !to.After(from) || to.Sub(from) > maxSchedulingRange {
+		return nil, fmt.Errorf("%w: to must be after from and at most 31 days later", types.ErrInvalidBooking)
+	}
+	link, err := s.repo.FindLinkByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	starts, _, err := s.freeHosts(ctx, link, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(link.DurationMinutes) * time.Minute
+	slots := make([]types.SchedulingSlot, 0, len(starts))
+	for _, start := range starts {
+		slots = append(slots, types.SchedulingSlot{StartsAt: start, EndsAt: start.Add(duration)})
+	}
+	return slots, nil
+}
+
+// Book books the meeting a prospect picked. The booking goes to the lead
+// of the link's tracking parameter, else to the open lead with the
+// invitee's email, else to a new lead from the link's source and team. A
+// team link gives the meeting to the free member with the fewest bookings.
+func (s *SchedulingLinkService) Book(ctx context.Context, token string, req types.LeadBookingRequest) (*types.LeadBookingConfirmation, error) {
+	name := strings.TrimSpace(req.Name)
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	switch {
+	case name == "":
+		return nil, fmt.Errorf("%w: name is required", types.ErrInvalidBooking)
+	case len(name) > 255:
+		return nil, fmt.Errorf("%w: name must be 255 characters or less", types.ErrInvalidBooking)
+	case err != nil || len(address.Address) > 255:
+		return nil, fmt.Errorf("%w: a valid email is required", types.ErrInvalidBooking)
+	case req.StartsAt.IsZero():
+		return nil, fmt.Errorf("%w: starts_at is required", types.ErrInvalidBooking)
+	}
+	email := strings.ToLower(address.Address)
+
+	link, err := s.repo.FindLinkByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	step := time.Duration(link.StepMinutes) * time.Minute
+	starts, free, err := s.freeHosts(ctx, link, req.StartsAt, req.StartsAt.Add(step))
+	if err != nil {
+		return nil, err
+	}
+	if len(starts) == 0 || !starts[0].Equal(req.StartsAt) {
+		return nil, types.ErrSlotUnavailable
+	}
+	hosts := free[req.StartsAt.Unix()]
+	if len(hosts) > 1 {
+		counts, err := s.repo.BookingCounts(ctx, link.ID)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(hosts, func(i, j int) bool { return counts[hosts[i]] < counts[hosts[j]] })
+	}
+
+	lead, attribution, err := s.bookingLead(ctx, link, req.LeadID, name, email, req.Notes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	booking := types.LeadBooking{
+		ID:             uuid.New(),
+		OrganizationID: link.OrganizationID,
+		LinkID:         &link.ID,
+		LeadID:         lead,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.StartsAt.Add(time.Duration(link.DurationMinutes) * time.Minute),
+		InviteeName:    name,
+		InviteeEmail:   email,
+		Notes:          req.Notes,
+		Attribution:    attribution,
+		CreatedAt:      now,
+	}
+	buffer := time.Duration(link.BufferMinutes) * time.Minute
+
+	var created *types.LeadBooking
+	for _, host := range hosts {
+		booking.HostUserID = host
+		created, err = s.repo.CreateBooking(ctx, booking, bookingActivity(booking, now), buffer)
+		// Another booking may have taken the host meanwhile; try the next one
+		if !errors.Is(err, types.ErrSlotUnavailable) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Booked meeting", "link_id", link.ID, "lead_id", created.LeadID, "host_user_id", created.HostUserID, "attribution", created.Attribution)
+	if s.eventBus != nil {
+		s.eventBus.Publish(ctx, "crm.lead.meeting_booked", map[string]interface{}{
+			"organization_id": created.OrganizationID,
+			"lead_id":         created.LeadID,
+			"booking_id":      created.ID,
+			"link_id":         link.ID,
+			"host_user_id":    created.HostUserID,
+			"starts_at":       created.StartsAt,
+			"attribution":     created.Attribution,
+		})
+	}
+	return &types.LeadBookingConfirmation{StartsAt: created.StartsAt, EndsAt: created.EndsAt}, nil
+}
+
+// freeHosts returns the slot starts in from..to, clipped to the link's
+// notice and horizon, and the hosts free at each, keyed by Unix time
+func (s *SchedulingLinkService) freeHosts(ctx context.Context, link *types.SchedulingLink, from, to time.Time) ([]time.Time, map[int64][]uuid.UUID, error) {
+	now := s.now()
+	if earliest := now.Add(time.Duration(link.MinNoticeMinutes) * time.Minute); from.Before(earliest) {
+		from = earliest
+	}
+	if latest := now.AddDate(0, 0, link.HorizonDays); to.After(latest) {
+		to = latest
+	}
+	free := make(map[int64][]uuid.UUID)
+	if !to.After(from) {
+		return []time.Time{}, free, nil
+	}
+
+	hosts, err := s.hosts(ctx, link)
+	if err != nil || len(hosts) == 0 {
+		return []time.Time{}, free, err
+	}
+	cal, err := s.calendar(ctx, link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	duration := time.Duration(link.DurationMinutes) * time.Minute
+	step := time.Duration(link.StepMinutes) * time.Minute
+	buffer := time.Duration(link.BufferMinutes) * time.Minute
+
+	busy := map[uuid.UUID][]calendar.Span{}
+	if s.busy != nil {
+		busy, err = s.busy.Busy(ctx, link.OrganizationID, hosts, from.Add(-buffer), to.Add(duration+buffer))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	starts := []time.Time{}
+	for _, host := range hosts {
+		spans := make([]calendar.Span, 0, len(busy[host]))
+		for _, span := range busy[host] {
+			spans = append(spans, calendar.Span{Start: span.Start.Add(-buffer), End: span.End.Add(buffer)})
+		}
+		for _, start := range cal.Slots(from, to, duration, step, spans) {
+			key := start.Unix()
+			if _, ok := free[key]; !ok {
+				starts = append(starts, start)
+			}
+			free[key] = append(free[key], host)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts, free, nil
+}
+
+// hosts returns the user of a user link or, in order, the members of a
+// team link's active team, or its leader when it has no members
+func (s *SchedulingLinkService) hosts(ctx context.Context, link *types.SchedulingLink) ([]uuid.UUID, error) {
+	if link.UserID != nil {
+		return []uuid.UUID{*link.UserID}, nil
+	}
+
+	team, err := s.teamRepo.FindByID(ctx, *link.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduling link team: %w", err)
+	}
+	if !team.IsActive || team.DeletedAt != nil {
+		return nil, nil
+	}
+	if len(team.MemberIDs) == 0 && team.TeamLeaderID != nil {
+		return []uuid.UUID{*team.TeamLeaderID}, nil
+	}
+	return team.MemberIDs, nil
+}
+
+// calendar returns the business hours of the link's team or, for a user
+// link, of the organization
+func (s *SchedulingLinkService) calendar(ctx context.Context, link *types.SchedulingLink) (*calendar.Calendar, error) {
+	if s.calendars == nil {
+		return calendar.Always(), nil
+	}
+	cal, err := s.calendars.Resolve(ctx, link.OrganizationID, link.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve business calendar: %w", err)
+	}
+	return cal, nil
+}
+
+// bookingLead finds or creates the lead a booking is attributed to
+func (s *SchedulingLinkService) bookingLead(ctx context.Context, link *types.SchedulingLink, trackedID *uuid.UUID, name, email string, notes *string) (uuid.UUID, types.LeadBookingAttribution, error) {
+	if trackedID != nil {
+		lead, err := s.leadRepo.FindByID(ctx, *trackedID)
+		switch {
+		case err == nil && lead.OrganizationID == link.OrganizationID:
+			return lead.ID, types.LeadBookingTracking, nil
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return uuid.Nil, "", fmt.Errorf("failed to find tracked lead: %w", err)
+		}
+		// An unknown or foreign lead is ignored rather than revealed
+	}
+
+	lead, err := s.leadRepo.FindOpenByEmailSince(ctx, link.OrganizationID, email, time.Time{})
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to match lead: %w", err)
+	}
+	if lead != nil {
+		return lead.ID, types.LeadBookingEmail, nil
+	}
+
+	req := types.LeadCreateRequest{
+		Name:        truncateUTF8(link.Name+": "+name, maxLeadEmailSummary),
+		ContactName: &name,
+		Email:       &email,
+		SourceID:    link.SourceID,
+		TeamID:      link.TeamID,
+		Description: notes,
+		Active:      true,
+	}
+	created, err := s.leadService.CreateLead(ctx, link.OrganizationID, req)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to create lead from booking: %w", err)
+	}
+	return created.ID, types.LeadBookingCreated, nil
+}
+
+// bookingActivity is the planned meeting on the lead's timeline, assigned to
+// the host
+func bookingActivity(booking types.LeadBooking, now time.Time) types.Activity {
+	resModel := "leads"
+	return types.Activity{
+		ID:             uuid.New(),
+		OrganizationID: booking.OrganizationID,
+		ActivityType:   types.ActivityTypeMeeting,
+		Summary:        truncateUTF8("Meeting with "+booking.InviteeName, maxLeadEmailSummary),
+		Note:           booking.Notes,
+		DateDeadline:   &booking.StartsAt,
+		UserID:         &booking.HostUserID,
+		AssignedTo:     &booking.HostUserID,
+		ResModel:       &resModel,
+		ResID:          &booking.LeadID,
+		State:          types.ActivityStatePlanned,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
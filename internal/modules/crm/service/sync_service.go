@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSyncLimit and MaxSyncLimit bound the changes per entity of a
+	// pull
+	DefaultSyncLimit = 100
+	MaxSyncLimit     = 500
+	// MaxSyncOps bounds the operations of one round trip
+	MaxSyncOps = 200
+	// SyncLag holds back changes this recent from pulls. Writes committed
+	// late or stamped by a server whose clock is behind would otherwise be
+	// stamped before a cursor already handed out, and never pulled.
+	SyncLag = 5 * time.Second
+)
+
+// SyncLeadService, SyncContactService and SyncActivityService are the
+// services the app's records are read and written through, so that their
+// validation, permission checks and events apply to offline writes too
+type SyncLeadService interface {
+	CreateLead(ctx context.Context, orgID uuid.UUID, req types.LeadCreateRequest) (types.Lead, error)
+	GetLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (types.Lead, error)
+	UpdateLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID, req types.LeadUpdateRequest) (types.Lead, error)
+	DeleteLead(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error
+}
+
+type SyncContactService interface {
+	CreateContact(ctx context.Context, req ContactRequest) (*types.Contact, error)
+	GetContact(ctx context.Context, id uuid.UUID) (*types.Contact, error)
+	UpdateContact(ctx context.Context, id uuid.UUID, req ContactUpdateRequest) (*types.Contact, error)
+	DeleteContact(ctx context.Context, id uuid.UUID) error
+}
+
+type SyncActivityService interface {
+	CreateActivity(ctx context.Context, req types.ActivityCreateRequest) (*types.Activity, error)
+	GetActivity(ctx context.Context, id uuid.UUID) (*types.Activity, error)
+	UpdateActivity(ctx context.Context, id uuid.UUID, req types.ActivityUpdateRequest) (*types.Activity, error)
+	DeleteActivity(ctx context.Context, id uuid.UUID) error
+}
+
+// SyncService keeps the field sales app's offline copy of leads, contacts
+// and activities in step: the app pulls what changed since its cursor and
+// pushes the writes it made offline, both in one round trip
+type SyncService struct {
+	repo        types.SyncRepository
+	leads       SyncLeadService
+	contacts    SyncContactService
+	activities  SyncActivityService
+	authService auth.LegacyAuthService
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewSyncService(repo types.SyncRepository, leads SyncLeadService, contacts SyncContactService, activities SyncActivityService, authService auth.LegacyAuthService) *SyncService {
+	return &SyncService{
+		repo:        repo,
+		leads:       leads,
+		contacts:    contacts,
+		activities:  activities,
+		authService: authService,
+		logger:      slog.Default().With("service", "sync"),
+		now:         time.Now,
+	}
+}
+
+// Sync applies the app's operations in order, then reads the changes of
+// each entity it pulls. An operation that fails validation is rejected and
+// the next ones still run; the round trip fails only when operations cannot
+// be recorded, and as applied ones are not applied again, the app sends it
+// again as it was.
+func (s *SyncService) Sync(ctx context.Context, orgID uuid.UUID, req types.SyncRequest) (*types.SyncResponse, error) {
+	if len(req.Ops) > MaxSyncOps {
+		return nil, fmt.Errorf("%w: at most %d operations per request", types.ErrInvalidSync, MaxSyncOps)
+	}
+	for entity := range req.Pull {
+		if !entity.IsValid() {
+			return nil, fmt.Errorf("%w: unknown entity %q", types.ErrInvalidSync, entity)
+		}
+	}
+
+	var userID *uuid.UUID
+	if id, err := s.authService.GetUserID(ctx); err == nil {
+		userID = &id
+	}
+
+	resp := &types.SyncResponse{
+		Results: make([]types.SyncOpResult, 0, len(req.Ops)),
+		Changes: map[types.SyncEntity]types.SyncPage{},
+	}
+	for _, op := range req.Ops {
+		result, err := s.applyOp(ctx, orgID, userID, op)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	for _, entity := range types.SyncEntities {
+		pull, ok := req.Pull[entity]
+		if !ok {
+			continue
+		}
+		page, err := s.Changes(ctx, orgID, entity, pull, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		resp.Changes[entity] = *page
+	}
+	resp.ServerTime = s.now()
+	return resp, nil
+}
+
+// Changes returns a page of an entity's records written or deleted since
+// the pull's cursor, in the order they changed. A record changed again
+// while the app pages through shows up again later, so the app applies
+// changes as they come.
+func (s *SyncService) Changes(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, pull types.SyncPull, limit int) (*types.SyncPage, error) {
+	if !entity.IsValid() {
+		return nil, fmt.Errorf("%w: unknown entity %q", types.ErrInvalidSync, entity)
+	}
+	if err := s.authService.CheckPermission(ctx, "crm:"+string(entity)+":read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if limit <= 0 {
+		limit = DefaultSyncLimit
+	}
+	if limit > MaxSyncLimit {
+		limit = MaxSyncLimit
+	}
+	after, err := decodeSyncCursor(pull.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more than asked tells whether more follow
+	markers, err := s.repo.Changes(ctx, orgID, entity, after, s.now().Add(-SyncLag), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &types.SyncPage{Entity: entity, Changes: []types.SyncChange{}, Cursor: pull.Cursor}
+	if len(markers) > limit {
+		markers = markers[:limit]
+		page.HasMore = true
+	}
+
+	for _, marker := range markers {
+		change := types.SyncChange{ID: marker.ID, Deleted: marker.Deleted, ChangedAt: marker.ChangedAt}
+		if !marker.Deleted {
+			record, err := s.getRecord(ctx, orgID, entity, marker.ID)
+			if err != nil {
+				return nil, err
+			}
+			if change.Record, err = projectSyncRecord(record, pull.Fields); err != nil {
+				return nil, err
+			}
+		}
+		page.Changes = append(page.Changes, change)
+	}
+	if len(markers) > 0 {
+		page.Cursor = encodeSyncCursor(markers[len(markers)-1])
+	}
+	return page, nil
+}
+
+// applyOp applies one operation, or returns the outcome it had when it was
+// applied before. It only fails when the outcome cannot be recorded.
+func (s *SyncService) applyOp(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, op types.SyncOp) (types.SyncOpResult, error) {
+	result := types.SyncOpResult{ClientID: op.ClientID}
+	rejected := func(msg string) (types.SyncOpResult, error) {
+		result.Status = types.SyncRejected
+		result.Error = msg
+		return result, nil
+	}
+	switch {
+	case op.ClientID == uuid.Nil:
+		return rejected("client_id is required")
+	case !op.Entity.IsValid():
+		return rejected(fmt.Sprintf("unknown entity %q", op.Entity))
+	case !op.Action.IsValid():
+		return rejected(fmt.Sprintf("unknown action %q", op.Action))
+	case op.Action != types.SyncDelete && len(op.Data) == 0:
+		return rejected("data is required")
+	}
+
+	applied, err := s.repo.FindAppliedOp(ctx, orgID, op.ClientID)
+	if err != nil {
+		return result, err
+	}
+	if applied != nil {
+		result.Status = types.SyncApplied
+		result.Replayed = true
+		result.ID = &applied.RecordID
+		if applied.Action != types.SyncDelete {
+			if record, err := s.getRecord(ctx, orgID, applied.Entity, applied.RecordID); err == nil {
+				result.Record, _ = projectSyncRecord(record, nil)
+			}
+		}
+		return result, nil
+	}
+
+	var recordID uuid.UUID
+	var record interface{}
+	if op.Action == types.SyncCreate {
+		recordID, record, err = s.createRecord(ctx, orgID, op.Entity, op.Data)
+		if err != nil {
+			return rejected(err.Error())
+		}
+	} else {
+		target, msg, err := s.resolveTarget(ctx, orgID, op)
+		if err != nil {
+			return result, err
+		}
+		if msg != "" {
+			return rejected(msg)
+		}
+		result.ID = &target
+
+		current, err := s.repo.FindRecord(ctx, orgID, op.Entity, target)
+		if err != nil {
+			return result, err
+		}
+		switch {
+		case current == nil || current.Deleted:
+			// A record already gone is as good as deleted; one to update
+			// cannot be brought back
+			if op.Action == types.SyncUpdate {
+				result.Status = types.SyncConflict
+				result.Error = "record was deleted"
+				return result, nil
+			}
+		case !op.Force && op.BaseUpdatedAt != nil && current.ChangedAt.After(*op.BaseUpdatedAt):
+			result.Status = types.SyncConflict
+			result.Error = "record changed since it was last synced"
+			if latest, err := s.getRecord(ctx, orgID, op.Entity, target); err == nil {
+				result.Record, _ = projectSyncRecord(latest, nil)
+			}
+			return result, nil
+		case op.Action == types.SyncUpdate:
+			if record, err = s.updateRecord(ctx, orgID, op.Entity, target, op.Data); err != nil {
+				return rejected(err.Error())
+			}
+		default:
+			if err := s.deleteRecord(ctx, orgID, op.Entity, target); err != nil {
+				return rejected(err.Error())
+			}
+		}
+		recordID = target
+	}
+
+	if err := s.repo.RecordAppliedOp(ctx, types.SyncAppliedOp{
+		OrganizationID: orgID,
+		ClientID:       op.ClientID,
+		UserID:         userID,
+		Entity:         op.Entity,
+		Action:         op.Action,
+		RecordID:       recordID,
+		AppliedAt:      s.now(),
+	}); err != nil {
+		return result, err
+	}
+
+	result.Status = types.SyncApplied
+	result.ID = &recordID
+	if record != nil {
+		if result.Record, err = projectSyncRecord(record, nil); err != nil {
+			s.logger.Warn("Failed to encode synced record", "entity", op.Entity, "id", recordID, "error", err)
+		}
+	}
+	return result, nil
+}
+
+// resolveTarget returns the record an update or delete is for, named by ID
+// or by the client ID of the operation that created it. A message is
+// returned for operations naming no record they may change.
+func (s *SyncService) resolveTarget(ctx context.Context, orgID uuid.UUID, op types.SyncOp) (uuid.UUID, string, error) {
+	if op.ID != nil {
+		return *op.ID, "", nil
+	}
+	if op.Ref == nil {
+		return uuid.Nil, "id or ref is required", nil
+	}
+	created, err := s.repo.FindAppliedOp(ctx, orgID, *op.Ref)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if created == nil || created.Action != types.SyncCreate || created.Entity != op.Entity {
+		return uuid.Nil, "ref names no " + string(op.Entity) + " created by an earlier operation", nil
+	}
+	return created.RecordID, "", nil
+}
+
+func (s *SyncService) getRecord(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, id uuid.UUID) (interface{}, error) {
+	switch entity {
+	case types.SyncLeads:
+		lead, err := s.leads.GetLead(ctx, orgID, id)
+		return lead, err
+	case types.SyncContacts:
+		return s.contacts.GetContact(ctx, id)
+	default:
+		return s.activities.GetActivity(ctx, id)
+	}
+}
+
+func (s *SyncService) createRecord(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, data json.RawMessage) (uuid.UUID, interface{}, error) {
+	switch entity {
+	case types.SyncLeads:
+		var req types.LeadCreateRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return uuid.Nil, nil, err
+		}
+		lead, err := s.leads.CreateLead(ctx, orgID, req)
+		return lead.ID, lead, err
+	case types.SyncContacts:
+		var req ContactRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return uuid.Nil, nil, err
+		}
+		req.OrganizationID = orgID
+		contact, err := s.contacts.CreateContact(ctx, req)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+		return contact.ID, contact, nil
+	default:
+		var req types.ActivityCreateRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return uuid.Nil, nil, err
+		}
+		activity, err := s.activities.CreateActivity(ctx, req)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+		return activity.ID, activity, nil
+	}
+}
+
+func (s *SyncService) updateRecord(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, id uuid.UUID, data json.RawMessage) (interface{}, error) {
+	switch entity {
+	case types.SyncLeads:
+		var req types.LeadUpdateRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return nil, err
+		}
+		lead, err := s.leads.UpdateLead(ctx, orgID, id, req)
+		return lead, err
+	case types.SyncContacts:
+		var req ContactUpdateRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return nil, err
+		}
+		return s.contacts.UpdateContact(ctx, id, req)
+	default:
+		var req types.ActivityUpdateRequest
+		if err := decodeSyncData(data, &req); err != nil {
+			return nil, err
+		}
+		return s.activities.UpdateActivity(ctx, id, req)
+	}
+}
+
+func (s *SyncService) deleteRecord(ctx context.Context, orgID uuid.UUID, entity types.SyncEntity, id uuid.UUID) error {
+	switch entity {
+	case types.SyncLeads:
+		return s.leads.DeleteLead(ctx, orgID, id)
+	case types.SyncContacts:
+		return s.contacts.DeleteContact(ctx, id)
+	default:
+		return s.activities.DeleteActivity(ctx, id)
+	}
+}
+
+func decodeSyncData(data json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	return nil
+}
+
+// projectSyncRecord encodes a record as the app receives it, keeping only
+// the given fields when there are any. The ID and updated_at are always
+// kept, as the app needs them to apply the change and to write it back.
+func projectSyncRecord(record interface{}, fields []string) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+	if len(fields) == 0 {
+		return values, nil
+	}
+
+	row := make(map[string]interface{}, len(fields)+2)
+	for _, field := range append([]string{"id", "updated_at"}, fields...) {
+		row[field] = values[field]
+	}
+	return row, nil
+}
+
+// encodeSyncCursor encodes the position of a change as an opaque cursor
+func encodeSyncCursor(marker types.SyncMarker) string {
+	raw := strconv.FormatInt(marker.ChangedAt.UnixNano(), 10) + ":" + marker.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSyncCursor returns the position a cursor was at; the empty cursor
+// is before every change
+func decodeSyncCursor(cursor string) (types.SyncMarker, error) {
+	if cursor == "" {
+		return types.SyncMarker{ChangedAt: time.Unix(0, 0).UTC()}, nil
+	}
+	invalid := fmt.Errorf("%w: malformed cursor", types.ErrInvalidSync)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return types.SyncMarker{}, invalid
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return types.SyncMarker{}, invalid
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return types.SyncMarker{}, invalid
+	}
+	marker := types.SyncMarker{ChangedAt: time.Unix(0, n).UTC()}
+	if marker.ID, err = uuid.Parse(id); err != nil {
+		return types.SyncMarker{}, invalid
+	}
+	return marker, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+// syncAuth allows everything as one user
+type syncAuth struct{ userID uuid.UUID }
+
+func (a syncAuth) CheckPermission(context.Context, string) error { return nil }
+
+func (a syncAuth) GetOrganizationID(context.Context) (uuid.UUID, error) { return uuid.Nil, nil }
+
+func (a syncAuth) GetUserID(context.Context) (uuid.UUID, error) { return a.userID, nil }
+
+// syncStore keeps leads and the sync state in memory, each write stamped
+// with the store's clock
+type syncStore struct {
+	now     time.Time
+	leads   map[uuid.UUID]types.Lead
+	ops     map[uuid.UUID]types.SyncAppliedOp
+	creates int
+}
+
+func newSyncStore(now time.Time) *syncStore {
+	return &syncStore{now: now, leads: map[uuid.UUID]types.Lead{}, ops: map[uuid.UUID]types.SyncAppliedOp{}}
+}
+
+func (s *syncStore) Changes(_ context.Context, _ uuid.UUID, _ types.SyncEntity, after types.SyncMarker, until time.Time, limit int) ([]types.SyncMarker, error) {
+	var markers []types.SyncMarker
+	for _, lead := range s.leads {
+		m := types.SyncMarker{ID: lead.ID, ChangedAt: lead.UpdatedAt, Deleted: lead.DeletedAt != nil}
+		later := m.ChangedAt.After(after.ChangedAt) || (m.ChangedAt.Equal(after.ChangedAt) && m.ID.String() > after.ID.String())
+		if later && !m.ChangedAt.After(until) {
+			markers = append(markers, m)
+		}
+	}
+	sort.Slice(markers, func(i, j int) bool {
+		if !markers[i].ChangedAt.Equal(markers[j].ChangedAt) {
+			return markers[i].ChangedAt.Before(markers[j].ChangedAt)
+		}
+		return markers[i].ID.String() < markers[j].ID.String()
+	})
+	if len(markers) > limit {
+		markers = markers[:limit]
+	}
+	return markers, nil
+}
+
+func (s *syncStore) FindRecord(_ context.Context, _ uuid.UUID, _ types.SyncEntity, id uuid.UUID) (*types.SyncMarker, error) {
+	lead, ok := s.leads[id]
+	if !ok {
+		return nil, nil
+	}
+	return &types.SyncMarker{ID: id, ChangedAt: lead.UpdatedAt, Deleted: lead.DeletedAt != nil}, nil
+}
+
+func (s *syncStore) FindAppliedOp(_ context.Context, _, clientID uuid.UUID) (*types.SyncAppliedOp, error) {
+	if op, ok := s.ops[clientID]; ok {
+		return &op, nil
+	}
+	return nil, nil
+}
+
+func (s *syncStore) RecordAppliedOp(_ context.Context, op types.SyncAppliedOp) error {
+	if _, ok := s.ops[op.ClientID]; !ok {
+		s.ops[op.ClientID] = op
+	}
+	return nil
+}
+
+func (s *syncStore) CreateLead(_ context.Context, orgID uuid.UUID, req types.LeadCreateRequest) (types.Lead, error) {
+	if req.Name == "" {
+		return types.Lead{}, errors.New("name is required")
+	}
+	s.creates++
+	lead := types.Lead{ID: uuid.New(), OrganizationID: orgID, Name: req.Name, UpdatedAt: s.now}
+	s.leads[lead.ID] = lead
+	return lead, nil
+}
+
+func (s *syncStore) GetLead(_ context.Context, _ uuid.UUID, id uuid.UUID) (types.Lead, error) {
+	lead, ok := s.leads[id]
+	if !ok {
+		return types.Lead{}, errors.New("lead not found")
+	}
+	return lead, nil
+}
+
+func (s *syncStore) UpdateLead(_ context.Context, _ uuid.UUID, id uuid.UUID, req types.LeadUpdateRequest) (types.Lead, error) {
+	lead := s.leads[id]
+	if req.Name != nil {
+		lead.Name = *req.Name
+	}
+	lead.UpdatedAt = s.now
+	s.leads[id] = lead
+	return lead, nil
+}
+
+func (s *syncStore) DeleteLead(_ context.Context, _ uuid.UUID, id uuid.UUID) error {
+	lead := s.leads[id]
+	lead.DeletedAt, lead.UpdatedAt = &s.now, s.now
+	s.leads[id] = lead
+	return nil
+}
+
+func newTestSyncService(store *syncStore) *SyncService {
+	svc := NewSyncService(store, store, nil, nil, syncAuth{userID: uuid.New()})
+	svc.now = func() time.Time { return store.now }
+	return svc
+}
+
+func leadOp(action types.SyncAction, data string) types.SyncOp {
+	op := types.SyncOp{ClientID: uuid.New(), Entity: types.SyncLeads, Action: action}
+	if data != "" {
+		op.Data = json.RawMessage(data)
+	}
+	return op
+}
+
+func TestSyncAppliesOperationsOnce(t *testing.T) {
+	store := newSyncStore(time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC))
+	svc := newTestSyncService(store)
+	orgID := uuid.New()
+
+	create := leadOp(types.SyncCreate, `{"name":"Acme"}`)
+	rename := leadOp(types.SyncUpdate, `{"name":"Acme Corp"}`)
+	rename.Ref = &create.ClientID
+	invalid := leadOp(types.SyncCreate, `{}`)
+
+	resp, err := svc.Sync(context.Background(), orgID, types.SyncRequest{Ops: []types.SyncOp{create, rename, invalid}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, types.SyncApplied, resp.Results[0].Status)
+	// The update named the lead created offline by its operation
+	assert.Equal(t, types.SyncApplied, resp.Results[1].Status)
+	assert.Equal(t, resp.Results[0].ID, resp.Results[1].ID)
+	assert.Equal(t, "Acme Corp", resp.Results[1].Record["name"])
+	assert.Equal(t, types.SyncRejected, resp.Results[2].Status)
+
+	// Sent again after a dropped connection, nothing is created twice
+	resp, err = svc.Sync(context.Background(), orgID, types.SyncRequest{Ops: []types.SyncOp{create}})
+	require.NoError(t, err)
+	assert.True(t, resp.Results[0].Replayed)
+	assert.Equal(t, 1, store.creates)
+	assert.Len(t, store.leads, 1)
+}
+
+func TestSyncDetectsConflicts(t *testing.T) {
+	seen := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	store := newSyncStore(seen.Add(time.Hour))
+	svc := newTestSyncService(store)
+	lead := types.Lead{ID: uuid.New(), Name: "Changed on the web", UpdatedAt: seen.Add(time.Minute)}
+	store.leads[lead.ID] = lead
+
+	update := leadOp(types.SyncUpdate, `{"name":"Changed offline"}`)
+	update.ID, update.BaseUpdatedAt = &lead.ID, &seen
+	resp, err := svc.Sync(context.Background(), uuid.New(), types.SyncRequest{Ops: []types.SyncOp{update}})
+	require.NoError(t, err)
+	assert.Equal(t, types.SyncConflict, resp.Results[0].Status)
+	assert.Equal(t, "Changed on the web", resp.Results[0].Record["name"])
+	assert.Equal(t, "Changed on the web", store.leads[lead.ID].Name)
+
+	// The app resolves the conflict by keeping its change
+	update.ClientID, update.Force = uuid.New(), true
+	resp, err = svc.Sync(context.Background(), uuid.New(), types.SyncRequest{Ops: []types.SyncOp{update}})
+	require.NoError(t, err)
+	assert.Equal(t, types.SyncApplied, resp.Results[0].Status)
+	assert.Equal(t, "Changed offline", store.leads[lead.ID].Name)
+
+	// Deleting a lead already gone is applied
+	gone := uuid.New()
+	remove := leadOp(types.SyncDelete, "")
+	remove.ID = &gone
+	resp, err = svc.Sync(context.Background(), uuid.New(), types.SyncRequest{Ops: []types.SyncOp{remove}})
+	require.NoError(t, err)
+	assert.Equal(t, types.SyncApplied, resp.Results[0].Status)
+}
+
+func TestSyncChangesPagesThroughSparseRecords(t *testing.T) {
+	start := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	store := newSyncStore(start.Add(time.Hour))
+	svc := newTestSyncService(store)
+	deletedAt := start.Add(2 * time.Minute)
+	for i, name := range []string{"First", "Second", "Third"} {
+		lead := types.Lead{ID: uuid.New(), Name: name, UpdatedAt: start.Add(time.Duration(i) * time.Minute)}
+		if name == "Third" {
+			lead.DeletedAt = &deletedAt
+		}
+		store.leads[lead.ID] = lead
+	}
+	// Too recent to be handed out yet
+	recent := types.Lead{ID: uuid.New(), Name: "Recent", UpdatedAt: store.now.Add(-time.Second)}
+	store.leads[recent.ID] = recent
+
+	pull := types.SyncPull{Fields: []string{"name"}}
+	page, err := svc.Changes(context.Background(), uuid.New(), types.SyncLeads, pull, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 2)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, map[string]interface{}{
+		"id": page.Changes[0].ID.String(), "updated_at": "2025-09-01T09:00:00Z", "name": "First",
+	}, page.Changes[0].Record)
+
+	pull.Cursor = page.Cursor
+	page, err = svc.Changes(context.Background(), uuid.New(), types.SyncLeads, pull, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 1)
+	assert.False(t, page.HasMore)
+	assert.True(t, page.Changes[0].Deleted)
+	assert.Nil(t, page.Changes[0].Record)
+
+	// Nothing new keeps the cursor where it is
+	pull.Cursor = page.Cursor
+	page, err = svc.Changes(context.Background(), uuid.New(), types.SyncLeads, pull, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Changes)
+	assert.Equal(t, pull.Cursor, page.Cursor)
+
+	_, err = svc.Changes(context.Background(), uuid.New(), types.SyncLeads, types.SyncPull{Cursor: "not a cursor"}, 0)
+	assert.ErrorIs(t, err, types.ErrInvalidSync)
+}
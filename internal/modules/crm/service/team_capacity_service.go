@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+// maxUnavailabilityReason bounds the reason given for an unavailability period
+const maxUnavailabilityReason = 255
+
+// TeamCapacityService lets managers set how much automatic assignment each
+// user takes and when they are away, and shows the team's load against its
+// capacity
+type TeamCapacityService struct {
+	repo        types.TeamCapacityRepository
+	teamRepo    types.SalesTeamRepository
+	authService auth.LegacyAuthService
+	now         func() time.Time
+}
+
+func NewTeamCapacityService(repo types.TeamCapacityRepository, teamRepo types.SalesTeamRepository, authService auth.LegacyAuthService) *TeamCapacityService {
+	return &TeamCapacityService{
+		repo:        repo,
+		teamRepo:    teamRepo,
+		authService: authService,
+		now:         time.Now,
+	}
+}
+
+// checkTargetModel defaults an empty target model to leads and rejects
+// unknown ones
+func checkTargetModel(targetModel string) (string, error) {
+	switch types.AssignmentTargetModel(targetModel) {
+	case "":
+		return string(types.AssignmentTargetModelLeads), nil
+	case types.AssignmentTargetModelLeads, types.AssignmentTargetModelContacts, types.AssignmentTargetModelOpportunities:
+		return targetModel, nil
+	}
+	return "", fmt.Errorf("%w: unknown target model %q", types.ErrInvalidTeamCapacity, targetModel)
+}
+
+// GetTeamWorkload returns the load of the organization's members against
+// their capacity, or of the members of a sales team
+func (s *TeamCapacityService) GetTeamWorkload(ctx context.Context, orgID uuid.UUID, targetModel string, teamID *uuid.UUID) (*types.TeamWorkload, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:team_capacity:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	targetModel, err := checkTargetModel(targetModel)
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []uuid.UUID
+	if teamID != nil {
+		team, err := s.teamRepo.FindByID(ctx, *teamID)
+		if err != nil {
+			return nil, err
+		}
+		if team.OrganizationID != orgID || team.DeletedAt != nil {
+			return nil, errors.New("sales team not found or access denied")
+		}
+		userIDs = append([]uuid.UUID{}, team.MemberIDs...)
+	}
+
+	capacities, err := s.repo.ListCapacities(ctx, orgID, targetModel, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	periods, err := s.repo.ListUnavailability(ctx, orgID, userIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	workload := BuildTeamWorkload(capacities, periods, now)
+	workload.TeamID = teamID
+	workload.TargetModel = targetModel
+	return workload, nil
+}
+
+// BuildTeamWorkload derives the team dashboard from the members' capacities
+// and their unavailability periods that have not ended by now
+func BuildTeamWorkload(capacities []types.UserCapacity, periods []types.UserUnavailability, now time.Time) *types.TeamWorkload {
+	byUser := make(map[uuid.UUID][]types.UserUnavailability)
+	for _, period := range periods {
+		byUser[period.UserID] = append(byUser[period.UserID], period)
+	}
+
+	workload := &types.TeamWorkload{
+		Members:     make([]types.TeamMemberWorkload, 0, len(capacities)),
+		GeneratedAt: now,
+	}
+	for _, capacity := range capacities {
+		member := types.TeamMemberWorkload{UserCapacity: capacity}
+		if capacity.MaxCapacity > 0 {
+			utilization := math.Round(1000*float64(capacity.ActiveAssignments)/float64(capacity.MaxCapacity)) / 10
+			member.Utilization = &utilization
+			member.OverCapacity = capacity.ActiveAssignments > capacity.MaxCapacity
+		}
+
+		for _, period := range byUser[capacity.UserID] {
+			period := period
+			if period.InProgress(now) {
+				if member.CurrentAbsence == nil {
+					member.CurrentAbsence = &period
+				}
+			} else if period.StartsAt.After(now) && member.NextAbsence == nil {
+				member.NextAbsence = &period
+			}
+		}
+
+		member.AvailableNow = capacity.IsAvailable && member.CurrentAbsence == nil &&
+			(capacity.UnavailableUntil == nil || !capacity.UnavailableUntil.After(now))
+
+		workload.ActiveAssignments += capacity.ActiveAssignments
+		if member.AvailableNow {
+			workload.AvailableMembers++
+			workload.Capacity += capacity.MaxCapacity
+		}
+		workload.Members = append(workload.Members, member)
+	}
+	return workload
+}
+
+// findMember returns the capacity of a member of the organization
+func (s *TeamCapacityService) findMember(ctx context.Context, orgID, userID uuid.UUID, targetModel string) (*types.UserCapacity, error) {
+	capacities, err := s.repo.ListCapacities(ctx, orgID, targetModel, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(capacities) == 0 {
+		return nil, errors.New("user not found or access denied")
+	}
+	return &capacities[0], nil
+}
+
+// UpdateCapacity changes the capacity, weight and availability of a user for
+// a target model
+func (s *TeamCapacityService) UpdateCapacity(ctx context.Context, orgID, userID uuid.UUID, req types.UserCapacityUpdateRequest) (*types.UserCapacity, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:team_capacity:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	targetModel, err := checkTargetModel(req.TargetModel)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaxCapacity != nil && *req.MaxCapacity < 0 {
+		return nil, fmt.Errorf("%w: max_capacity must not be negative", types.ErrInvalidTeamCapacity)
+	}
+	if req.Weight != nil && *req.Weight < 1 {
+		return nil, fmt.Errorf("%w: weight must be at least 1", types.ErrInvalidTeamCapacity)
+	}
+
+	capacity, err := s.findMember(ctx, orgID, userID, targetModel)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaxCapacity != nil {
+		capacity.MaxCapacity = *req.MaxCapacity
+	}
+	if req.Weight != nil {
+		capacity.Weight = *req.Weight
+	}
+	if req.IsAvailable != nil {
+		capacity.IsAvailable = *req.IsAvailable
+		if capacity.IsAvailable {
+			capacity.UnavailableUntil = nil
+		}
+	}
+	if req.UnavailableUntil != nil {
+		capacity.UnavailableUntil = req.UnavailableUntil
+	}
+
+	if err := s.repo.SaveCapacity(ctx, orgID, *capacity); err != nil {
+		return nil, err
+	}
+	return capacity, nil
+}
+
+// ListUnavailability returns a user's unavailability periods that are in
+// progress or ahead
+func (s *TeamCapacityService) ListUnavailability(ctx context.Context, orgID, userID uuid.UUID) ([]types.UserUnavailability, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:team_capacity:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListUnavailability(ctx, orgID, []uuid.UUID{userID}, s.now())
+}
+
+// ScheduleUnavailability adds a period, such as a vacation, during which the
+// user takes no automatic assignments
+func (s *TeamCapacityService) ScheduleUnavailability(ctx context.Context, orgID, actorID, userID uuid.UUID, req types.UserUnavailabilityRequest) (*types.UserUnavailability, error) {
+	if err := s.authService.CheckPermission(ctx, "crm:team_capacity:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.StartsAt.IsZero() || req.EndsAt.IsZero():
+		return nil, fmt.Errorf("%w: starts_at and ends_at are required", types.ErrInvalidTeamCapacity)
+	case !req.EndsAt.After(req.StartsAt):
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", types.ErrInvalidTeamCapacity)
+	case !req.EndsAt.After(s.now()):
+		return nil, fmt.Errorf("%w: the period has already ended", types.ErrInvalidTeamCapacity)
+	case len(req.Reason) > maxUnavailabilityReason:
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", types.ErrInvalidTeamCapacity, maxUnavailabilityReason)
+	}
+
+	if _, err := s.findMember(ctx, orgID, userID, string(types.AssignmentTargetModelLeads)); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateUnavailability(ctx, types.UserUnavailability{
+		OrganizationID: orgID,
+		UserID:         userID,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Reason:         req.Reason,
+		CreatedBy:      &actorID,
+	})
+}
+
+// DeleteUnavailability removes one of a user's unavailability periods
+func (s *TeamCapacityService) DeleteUnavailability(ctx context.Context, orgID, userID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "crm:team_capacity:update"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteUnavailability(ctx, orgID, userID, id)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/crm/types"
+)
+
+func TestBuildTeamWorkloadComparesLoadWithCapacity(t *testing.T) {
+	now := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC)
+	busy, away, unlimited, paused := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	until := now.Add(2 * time.Hour)
+	capacities := []types.UserCapacity{
+		{UserID: busy, ActiveAssignments: 12, MaxCapacity: 10, Weight: 1, IsAvailable: true},
+		{UserID: away, ActiveAssignments: 3, MaxCapacity: 8, Weight: 2, IsAvailable: true},
+		{UserID: unlimited, ActiveAssignments: 5, Weight: 1, IsAvailable: true},
+		{UserID: paused, ActiveAssignments: 1, MaxCapacity: 4, Weight: 1, IsAvailable: false, UnavailableUntil: &until},
+	}
+	vacation := types.UserUnavailability{ID: uuid.New(), UserID: away, StartsAt: now.AddDate(0, 0, -2), EndsAt: now.AddDate(0, 0, 3)}
+	training := types.UserUnavailability{ID: uuid.New(), UserID: busy, StartsAt: now.AddDate(0, 0, 7), EndsAt: now.AddDate(0, 0, 8)}
+
+	workload := BuildTeamWorkload(capacities, []types.UserUnavailability{vacation, training}, now)
+	require.Len(t, workload.Members, 4)
+	assert.Equal(t, 21, workload.ActiveAssignments)
+	assert.Equal(t, 2, workload.AvailableMembers)
+	assert.Equal(t, 10, workload.Capacity)
+
+	assert.Equal(t, 120.0, *workload.Members[0].Utilization)
+	assert.True(t, workload.Members[0].OverCapacity)
+	assert.True(t, workload.Members[0].AvailableNow)
+	assert.Equal(t, &training, workload.Members[0].NextAbsence)
+
+	assert.Equal(t, 37.5, *workload.Members[1].Utilization)
+	assert.False(t, workload.Members[1].AvailableNow)
+	assert.Equal(t, &vacation, workload.Members[1].CurrentAbsence)
+
+	assert.Nil(t, workload.Members[2].Utilization)
+	assert.True(t, workload.Members[2].AvailableNow)
+
+	assert.False(t, workload.Members[3].AvailableNow)
+}
@@ -4,11 +4,24 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/expr"
+
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrInvalidAssignmentRule is returned for assignment rules that fail validation
+	ErrInvalidAssignmentRule = errors.New("invalid assignment rule")
+	// ErrNoMatchingAssignmentRule is returned when no active rule applies
+	ErrNoMatchingAssignmentRule = errors.New("no matching assignment rule found")
+	// ErrAssignmentConflict is returned when a rule's round robin position
+	// moved while an assignee was being chosen
+	ErrAssignmentConflict = errors.New("assignment rule changed during assignment")
+)
+
 // AssignmentRuleType represents the type of assignment rule
 type AssignmentRuleType string
 
@@ -17,6 +30,9 @@ const (
 	AssignmentRuleTypeWeighted   AssignmentRuleType = "weighted"
 	AssignmentRuleTypeTerritory  AssignmentRuleType = "territory"
 	AssignmentRuleTypeCustom     AssignmentRuleType = "custom"
+	// AssignmentRuleTypePool rules assign no one: they return the record to
+	// the pool of unassigned records, for instance leads left to rot
+	AssignmentRuleTypePool AssignmentRuleType = "pool"
 )
 
 // AssignmentTargetModel represents the target entity type
@@ -94,9 +110,34 @@ type AssignmentConfig struct {
 	// Territory
 	Territories []TerritoryAssignment `json:"territories,omitempty"`
 
-	// Custom
+	// Custom: Logic is an expression scoring each of Users for the lead,
+	// see CompileCustomLogic
 	Logic  string                 `json:"logic,omitempty"`
 	Params map[string]interface{} `json:"params,omitempty"`
+
+	// FallbackUsers take the assignments nobody on shift can take, whatever
+	// their working hours. Without any, those assignments go to the pool.
+	FallbackUsers []uuid.UUID `json:"fallback_users,omitempty"`
+}
+
+// CustomAssignmentVariables are the variables the logic of a custom rule
+// reads: the lead attributes given for the assignment, the load of the user
+// being scored (id, active_assignments, total_assignments, max_capacity,
+// weight, is_available and hours_since_assignment) and the rule's params
+var CustomAssignmentVariables = []string{"lead", "user", "params"}
+
+// CompileCustomLogic compiles the logic of a custom rule. The logic is run
+// for each of the rule's users: true or a positive number makes the user
+// eligible, and the eligible user with the highest number is assigned.
+func (ac AssignmentConfig) CompileCustomLogic() (*expr.Expression, error) {
+	if len(ac.Users) == 0 {
+		return nil, fmt.Errorf("%w: custom rules need users to choose from", ErrInvalidAssignmentRule)
+	}
+	logic, err := expr.Compile(ac.Logic, CustomAssignmentVariables...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: logic: %w", ErrInvalidAssignmentRule, err)
+	}
+	return logic, nil
 }
 
 // Value implements the driver.Valuer interface
@@ -168,6 +209,28 @@ type UserAssignmentLoad struct {
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// AssignmentRecord is an assignment chosen by a rule, to be recorded
+type AssignmentRecord struct {
+	OrganizationID uuid.UUID
+	RuleID         uuid.UUID
+	RuleName       string
+	TargetModel    string
+	TargetID       uuid.UUID
+	// AssignedToID is uuid.Nil when the record is returned to the pool
+	AssignedToID uuid.UUID
+	// PreviousAssignedToID is released of the lead's load when set
+	PreviousAssignedToID *uuid.UUID
+	Reason               string
+	// Cursor moves the rule's round robin position from From to To
+	Cursor *AssignmentCursor
+}
+
+// AssignmentCursor is a move of a round robin rule's position
+type AssignmentCursor struct {
+	From int
+	To   int
+}
+
 // Territory represents a territory definition
 type Territory struct {
 	ID             uuid.UUID   `json:"id" db:"id"`
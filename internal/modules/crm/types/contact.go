@@ -3,6 +3,9 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
 	"github.com/google/uuid"
 )
 
@@ -32,6 +35,7 @@ type ContactFilter struct {
 	Phone          *string
 	IsCustomer     *bool
 	IsVendor       *bool
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -100,16 +104,18 @@ type ContactTag struct {
 type ContactTagFilter struct {
 	OrganizationID uuid.UUID
 	Name           *string
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
 
 // AdvancedContactFilter represents advanced filtering criteria for contacts
 type AdvancedContactFilter struct {
-	OrganizationID uuid.UUID `json:"organization_id"`
-	SearchQuery    string    `json:"search_query,omitempty"`
-	Tags           []string  `json:"tags,omitempty"`
-	Segments       []string  `json:"segments,omitempty"`
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	SearchQuery    string             `json:"search_query,omitempty"`
+	MatchMode      database.MatchMode `json:"match_mode,omitempty"`
+	Tags           []string           `json:"tags,omitempty"`
+	Segments       []string           `json:"segments,omitempty"`
 	ScoreRange     struct {
 		Min int `json:"min,omitempty"`
 		Max int `json:"max,omitempty"`
@@ -118,8 +124,23 @@ type AdvancedContactFilter struct {
 		From time.Time `json:"from,omitempty"`
 		To   time.Time `json:"to,omitempty"`
 	} `json:"last_contacted,omitempty"`
-	Page     int `json:"page,omitempty"`
-	PageSize int `json:"page_size,omitempty"`
+	// Query is a boolean filter tree over the contact's fields; the service
+	// compiles it against the custom field definitions to Condition
+	Query     *query.Node      `json:"query,omitempty"`
+	Condition *query.Condition `json:"-"`
+	Page      int              `json:"page,omitempty"`
+	PageSize  int              `json:"page_size,omitempty"`
+}
+
+// Paginate defaults the page to the first and the page size to 20, which
+// is also used for sizes over 100
+func (f *AdvancedContactFilter) Paginate() {
+	if f.Page <= 0 {
+		f.Page = 1
+	}
+	if f.PageSize <= 0 || f.PageSize > 100 {
+		f.PageSize = 20
+	}
 }
 
 // CRMDashboard represents a comprehensive CRM dashboard
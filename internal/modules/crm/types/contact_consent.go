@@ -0,0 +1,143 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidConsent is returned for consent requests that fail validation
+	ErrInvalidConsent = errors.New("invalid consent")
+	// ErrConsentTokenNotFound is returned for unknown unsubscribe tokens and
+	// unknown, used or expired confirmation tokens
+	ErrConsentTokenNotFound = errors.New("consent link not found or expired")
+	// ErrConsentUnavailable is returned when a double opt-in confirmation
+	// cannot be emailed because no mailer or public URL is configured
+	ErrConsentUnavailable = errors.New("double opt-in confirmations cannot be emailed: no mailer or public URL is configured")
+)
+
+// ContactConsentChannel is a way of reaching a contact
+type ContactConsentChannel string
+
+const (
+	ContactConsentEmail ContactConsentChannel = "email"
+	ContactConsentSMS   ContactConsentChannel = "sms"
+	ContactConsentPhone ContactConsentChannel = "phone"
+)
+
+// ContactConsentChannels are every channel, in display order
+var ContactConsentChannels = []ContactConsentChannel{ContactConsentEmail, ContactConsentSMS, ContactConsentPhone}
+
+// IsValid reports whether the channel is known
+func (c ContactConsentChannel) IsValid() bool {
+	switch c {
+	case ContactConsentEmail, ContactConsentSMS, ContactConsentPhone:
+		return true
+	}
+	return false
+}
+
+// ContactConsentStatus is a contact's preference for a channel
+type ContactConsentStatus string
+
+const (
+	// ContactConsentUnknown is the status of channels the contact has not
+	// stated a preference for; it is never stored
+	ContactConsentUnknown ContactConsentStatus = "unknown"
+	// ContactConsentPending is an email opt-in waiting for the contact to
+	// confirm it
+	ContactConsentPending  ContactConsentStatus = "pending"
+	ContactConsentOptedIn  ContactConsentStatus = "opted_in"
+	ContactConsentOptedOut ContactConsentStatus = "opted_out"
+)
+
+// ContactConsentSource is who changed a consent
+type ContactConsentSource string
+
+const (
+	// ContactConsentSourceUser is a user recording the contact's preference
+	ContactConsentSourceUser ContactConsentSource = "user"
+	// ContactConsentSourceDoubleOptIn is the contact confirming an opt-in
+	ContactConsentSourceDoubleOptIn ContactConsentSource = "double_opt_in"
+	// ContactConsentSourceUnsubscribe is the contact following an
+	// unsubscribe link
+	ContactConsentSourceUnsubscribe ContactConsentSource = "unsubscribe_link"
+)
+
+// ContactConsent is a contact's preference for a channel
+type ContactConsent struct {
+	OrganizationID uuid.UUID             `json:"organization_id"`
+	ContactID      uuid.UUID             `json:"contact_id"`
+	Channel        ContactConsentChannel `json:"channel"`
+	Status         ContactConsentStatus  `json:"status"`
+	Source         ContactConsentSource  `json:"source,omitempty"`
+	OptedInAt      *time.Time            `json:"opted_in_at,omitempty"`
+	// ConfirmationSentAt and ConfirmedAt are the double opt-in timestamps:
+	// when the confirmation link was emailed and when the contact used it
+	ConfirmationSentAt *time.Time `json:"confirmation_sent_at,omitempty"`
+	ConfirmedAt        *time.Time `json:"confirmed_at,omitempty"`
+	OptedOutAt         *time.Time `json:"opted_out_at,omitempty"`
+	UpdatedBy          *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+
+	// ConfirmationTokenHash is the SHA-256 of a pending opt-in's
+	// confirmation token
+	ConfirmationTokenHash string `json:"-"`
+}
+
+// Allows reports whether the contact may be reached on the channel: every
+// channel the contact has not opted out of or is still to confirm an
+// opt-in for
+func (c ContactConsent) Allows() bool {
+	return c.Status != ContactConsentOptedOut && c.Status != ContactConsentPending
+}
+
+// ContactConsentEvent is a change of a contact's consent
+type ContactConsentEvent struct {
+	ID             uuid.UUID             `json:"id"`
+	OrganizationID uuid.UUID             `json:"organization_id"`
+	ContactID      uuid.UUID             `json:"contact_id"`
+	Channel        ContactConsentChannel `json:"channel"`
+	PreviousStatus ContactConsentStatus  `json:"previous_status"`
+	Status         ContactConsentStatus  `json:"status"`
+	Source         ContactConsentSource  `json:"source"`
+	// ActorID is the user who made a change from the application
+	ActorID *uuid.UUID `json:"actor_id,omitempty"`
+	// IPAddress and UserAgent are those the contact used a link with
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ContactConsentRequest records a contact's preference for a channel. An
+// email opt-in with DoubleOptIn stays pending until the contact confirms it
+// from the link emailed to them.
+type ContactConsentRequest struct {
+	Channel     ContactConsentChannel `json:"channel"`
+	Status      ContactConsentStatus  `json:"status"`
+	DoubleOptIn bool                  `json:"double_opt_in"`
+	Note        string                `json:"note"`
+}
+
+// ContactPreferences are a contact's preferences for every channel
+type ContactPreferences struct {
+	ContactID uuid.UUID        `json:"contact_id"`
+	Channels  []ContactConsent `json:"channels"`
+	// UnsubscribePath is the contact's public unsubscribe link, relative to
+	// the API's public URL
+	UnsubscribePath string `json:"unsubscribe_path"`
+}
+
+// PublicContactPreferences is what the unsubscribe page of a token shows
+type PublicContactPreferences struct {
+	Channels []PublicContactConsent `json:"channels"`
+}
+
+// PublicContactConsent is a channel's status on the unsubscribe page
+type PublicContactConsent struct {
+	Channel ContactConsentChannel `json:"channel"`
+	Status  ContactConsentStatus  `json:"status"`
+}
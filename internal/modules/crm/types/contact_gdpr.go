@@ -0,0 +1,113 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrContactGDPRRequestPending is returned when an anonymization of the
+// contact is already awaiting approval
+var ErrContactGDPRRequestPending = errors.New("an anonymization of the contact is already pending")
+
+// ErrContactAnonymized is returned when the contact was already anonymized
+var ErrContactAnonymized = errors.New("contact is already anonymized")
+
+// Kinds of GDPR requests
+const (
+	ContactGDPRKindExport    = "export"
+	ContactGDPRKindAnonymize = "anonymize"
+)
+
+// Statuses of GDPR requests. Exports are completed when made;
+// anonymizations stay pending until another user approves or rejects them.
+const (
+	ContactGDPRStatusPending   = "pending"
+	ContactGDPRStatusCompleted = "completed"
+	ContactGDPRStatusRejected  = "rejected"
+)
+
+// ContactGDPRSections are the sections of a contact's data export, in
+// archive order. Addresses are the contact's child contacts and the
+// shipping addresses of its orders and invoices; emails are the messages
+// ingested into its leads; consents are its communication preferences.
+var ContactGDPRSections = []string{
+	"contact",
+	"addresses",
+	"leads",
+	"activities",
+	"emails",
+	"invoices",
+	"sales_orders",
+	"delivery_stops",
+	"portal_account",
+	"support_tickets",
+	"return_requests",
+	"consents",
+	"consent_history",
+}
+
+// ContactGDPRRequest is the audit record of an export or anonymization of
+// a contact's personal data
+type ContactGDPRRequest struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id" db:"organization_id"`
+	ContactID      uuid.UUID        `json:"contact_id" db:"contact_id"`
+	Kind           string           `json:"kind" db:"kind"`
+	Status         string           `json:"status" db:"status"`
+	Reason         string           `json:"reason,omitempty" db:"reason"`
+	RequestedBy    uuid.UUID        `json:"requested_by" db:"requested_by"`
+	RequestedAt    time.Time        `json:"requested_at" db:"requested_at"`
+	ReviewedBy     *uuid.UUID       `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt     *time.Time       `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNote     string           `json:"review_note,omitempty" db:"review_note"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty" db:"completed_at"`
+	Summary        map[string]int64 `json:"summary,omitempty" db:"summary"`
+	SHA256         string           `json:"sha256,omitempty" db:"sha256"`
+}
+
+// ContactGDPRSection is one section of a contact's data export: the rows
+// held about the contact in one table, as a JSON array
+type ContactGDPRSection struct {
+	Name string          `json:"name"`
+	Rows int64           `json:"rows"`
+	Data json.RawMessage `json:"-"`
+}
+
+// ContactGDPRManifest describes the files of a contact's export archive
+type ContactGDPRManifest struct {
+	ContactID      uuid.UUID                 `json:"contact_id"`
+	OrganizationID uuid.UUID                 `json:"organization_id"`
+	RequestID      uuid.UUID                 `json:"request_id"`
+	ExportedAt     time.Time                 `json:"exported_at"`
+	ExportedBy     uuid.UUID                 `json:"exported_by"`
+	Files          []ContactGDPRManifestFile `json:"files"`
+}
+
+// ContactGDPRManifestFile is a section file of an export archive
+type ContactGDPRManifestFile struct {
+	Path    string `json:"path"`
+	Section string `json:"section"`
+	Rows    int64  `json:"rows"`
+}
+
+// ContactGDPRArchive is a contact's export archive, a zip of a manifest and
+// one JSON file per section
+type ContactGDPRArchive struct {
+	Request  *ContactGDPRRequest
+	Filename string
+	Data     []byte
+}
+
+// ContactAnonymizeRequest asks for a contact to be anonymized
+type ContactAnonymizeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ContactGDPRReview approves or rejects a pending anonymization; a
+// rejection needs a note
+type ContactGDPRReview struct {
+	Note string `json:"note"`
+}
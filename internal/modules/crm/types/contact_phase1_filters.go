@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -21,6 +23,7 @@ type SegmentFilter struct {
 	OrganizationID uuid.UUID
 	SegmentType    *string // static, dynamic
 	Name           *string
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -55,3 +55,43 @@ type ListDuplicatesResponse struct {
 	Limit      *int                `json:"limit,omitempty"`
 	Offset     *int                `json:"offset,omitempty"`
 }
+
+// ========== Contact Merge ==========
+
+// ContactMergeFields lists the contact fields a merge can take from either
+// contact
+var ContactMergeFields = []string{"name", "email", "phone", "street", "city", "state_id", "country_id"}
+
+// ContactDuplicateMatch represents a contact that may duplicate another one
+type ContactDuplicateMatch struct {
+	ContactID       uuid.UUID `json:"contact_id"`
+	Name            string    `json:"name"`
+	Email           *string   `json:"email,omitempty"`
+	Phone           *string   `json:"phone,omitempty"`
+	SimilarityScore float64   `json:"similarity_score"`
+	NameSimilarity  float64   `json:"name_similarity"`
+	MatchingFields  []string  `json:"matching_fields"`
+}
+
+// MergeContactRequest represents a request to merge a duplicate into a
+// surviving contact
+type MergeContactRequest struct {
+	DuplicateContactID uuid.UUID         `json:"duplicate_contact_id"`
+	Strategy           string            `json:"strategy"`                   // keep_master, keep_latest, custom
+	FieldSelections    map[string]string `json:"field_selections,omitempty"` // field -> master or duplicate
+	DryRun             bool              `json:"dry_run"`
+}
+
+// ContactMergeResult represents the outcome of a merge, or with DryRun what
+// the merge would do
+type ContactMergeResult struct {
+	MasterContactID    uuid.UUID              `json:"master_contact_id"`
+	DuplicateContactID uuid.UUID              `json:"duplicate_contact_id"`
+	DryRun             bool                   `json:"dry_run"`
+	Fields             map[string]interface{} `json:"fields"`
+	// Reassigned counts the leads, invoices, activities and relationships
+	// moved to the master contact
+	Reassigned           map[string]int64 `json:"reassigned"`
+	RelationshipsRemoved int64            `json:"relationships_removed"`
+	MergeHistoryID       *uuid.UUID       `json:"merge_history_id,omitempty"`
+}
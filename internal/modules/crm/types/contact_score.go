@@ -0,0 +1,168 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidContactScoreWeights wraps validation failures of score weights
+var ErrInvalidContactScoreWeights = errors.New("invalid contact score weights")
+
+// ContactScoreWeights are an organization's relative weights of the signals
+// a contact's engagement score is made of. Only their proportions matter.
+type ContactScoreWeights struct {
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	Recency        int        `json:"recency" db:"recency_weight"`
+	Frequency      int        `json:"frequency" db:"frequency_weight"`
+	ResponseRate   int        `json:"response_rate" db:"response_rate_weight"`
+	WonDeals       int        `json:"won_deals" db:"won_deals_weight"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	UpdatedBy      *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// DefaultContactScoreWeights returns the weights of organizations that did
+// not configure their own
+func DefaultContactScoreWeights(orgID uuid.UUID) ContactScoreWeights {
+	return ContactScoreWeights{
+		OrganizationID: orgID,
+		Recency:        30,
+		Frequency:      30,
+		ResponseRate:   20,
+		WonDeals:       20,
+	}
+}
+
+const (
+	// ContactScoreFrequencyTarget is the number of activities in the scoring
+	// window that earns the full frequency score
+	ContactScoreFrequencyTarget = 12
+	// ContactScoreWonDealsTarget is the number of won deals that earns the
+	// full won deals score
+	ContactScoreWonDealsTarget = 3
+)
+
+// Validate checks that each weight is between 0 and 100 and that not all
+// of them are zero
+func (w ContactScoreWeights) Validate() error {
+	for _, weight := range []struct {
+		name  string
+		value int
+	}{
+		{"recency", w.Recency}, {"frequency", w.Frequency},
+		{"response_rate", w.ResponseRate}, {"won_deals", w.WonDeals},
+	} {
+		if weight.value < 0 || weight.value > 100 {
+			return fmt.Errorf("%w: %s must be between 0 and 100", ErrInvalidContactScoreWeights, weight.name)
+		}
+	}
+	if w.Recency+w.Frequency+w.ResponseRate+w.WonDeals == 0 {
+		return fmt.Errorf("%w: at least one weight must be positive", ErrInvalidContactScoreWeights)
+	}
+	return nil
+}
+
+// ContactScoreWeightsRequest represents a request to change score weights
+type ContactScoreWeightsRequest struct {
+	Recency      int `json:"recency"`
+	Frequency    int `json:"frequency"`
+	ResponseRate int `json:"response_rate"`
+	WonDeals     int `json:"won_deals"`
+}
+
+// ContactEngagementSignals are the activity data a contact is scored from.
+// Activities count those logged on the contact and on its leads, in the
+// scoring window before At.
+type ContactEngagementSignals struct {
+	ContactID      uuid.UUID
+	OrganizationID uuid.UUID
+	At             time.Time
+	LastActivityAt *time.Time
+	Activities     int
+	// EmailsSent counts emails logged to the contact; EmailsReceived those
+	// it sent to an inbound mailbox
+	EmailsSent     int
+	EmailsReceived int
+	WonDeals       int
+	// OpenLeadProbability is the highest probability of the contact's
+	// open leads
+	OpenLeadProbability int
+}
+
+// Score scores the contact from its signals. Each signal scores from 0 to
+// 100: recency falls linearly to 0 over the window, frequency and won deals
+// grow to their targets, and the response rate is the share of sent emails
+// that were answered. The engagement score is their weighted average; the
+// response rate is left out, its weight shared by the others, when no
+// email was sent. The lead score is the probability of the most promising
+// open lead.
+func (s ContactEngagementSignals) Score(weights ContactScoreWeights, window time.Duration) ContactScoreSnapshot {
+	day := s.At.UTC()
+	snapshot := ContactScoreSnapshot{
+		ContactID:      s.ContactID,
+		OrganizationID: s.OrganizationID,
+		ScoredOn:       time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+		LeadScore:      min(max(s.OpenLeadProbability, 0), 100),
+		LastActivityAt: s.LastActivityAt,
+		CalculatedAt:   s.At,
+	}
+
+	if s.LastActivityAt != nil && window > 0 {
+		elapsed := max(s.At.Sub(*s.LastActivityAt), 0)
+		snapshot.RecencyScore = scorePercent(1 - float64(elapsed)/float64(window))
+	}
+	snapshot.FrequencyScore = scorePercent(float64(s.Activities) / ContactScoreFrequencyTarget)
+	snapshot.WonDealsScore = scorePercent(float64(s.WonDeals) / ContactScoreWonDealsTarget)
+
+	total := float64(weights.Recency*snapshot.RecencyScore + weights.Frequency*snapshot.FrequencyScore +
+		weights.WonDeals*snapshot.WonDealsScore)
+	weight := weights.Recency + weights.Frequency + weights.WonDeals
+	if s.EmailsSent > 0 {
+		response := scorePercent(float64(s.EmailsReceived) / float64(s.EmailsSent))
+		snapshot.ResponseScore = &response
+		total += float64(weights.ResponseRate * response)
+		weight += weights.ResponseRate
+	}
+	if weight > 0 {
+		snapshot.EngagementScore = int(math.Round(total / float64(weight)))
+	}
+
+	return snapshot
+}
+
+// scorePercent turns a ratio into a score from 0 to 100
+func scorePercent(ratio float64) int {
+	return int(math.Round(100 * min(max(ratio, 0), 1)))
+}
+
+// ContactScoreSnapshot is a contact's score on one day, kept for trends
+type ContactScoreSnapshot struct {
+	ContactID       uuid.UUID  `json:"contact_id" db:"contact_id"`
+	OrganizationID  uuid.UUID  `json:"organization_id" db:"organization_id"`
+	ScoredOn        time.Time  `json:"scored_on" db:"scored_on"`
+	EngagementScore int        `json:"engagement_score" db:"engagement_score"`
+	LeadScore       int        `json:"lead_score" db:"lead_score"`
+	RecencyScore    int        `json:"recency_score" db:"recency_score"`
+	FrequencyScore  int        `json:"frequency_score" db:"frequency_score"`
+	ResponseScore   *int       `json:"response_score,omitempty" db:"response_score"`
+	WonDealsScore   int        `json:"won_deals_score" db:"won_deals_score"`
+	LastActivityAt  *time.Time `json:"last_activity_at,omitempty" db:"last_activity_at"`
+	CalculatedAt    time.Time  `json:"calculated_at" db:"calculated_at"`
+}
+
+// ContactScoreHistoryFilter selects a contact's daily scores
+type ContactScoreHistoryFilter struct {
+	OrganizationID uuid.UUID
+	ContactID      uuid.UUID
+	From           time.Time
+	To             time.Time
+}
+
+// ContactScoreRun summarizes one pass of the score recalculation worker
+type ContactScoreRun struct {
+	ScoredOn time.Time `json:"scored_on"`
+	Scored   int       `json:"scored"`
+}
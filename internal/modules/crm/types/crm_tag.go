@@ -0,0 +1,72 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCRMTagNotFound is returned when a tag does not exist in the
+	// organization
+	ErrCRMTagNotFound = errors.New("tag not found")
+	// ErrInvalidCRMTag is returned for invalid tag names or colors, and when
+	// tagging a record with tags that do not exist
+	ErrInvalidCRMTag = errors.New("invalid tag")
+	// ErrCRMTagExists is returned when the organization already has a tag of
+	// the name, ignoring case
+	ErrCRMTagExists = errors.New("tag already exists")
+	// ErrTaggedRecordNotFound is returned when tagging a lead or contact that
+	// does not exist in the organization
+	ErrTaggedRecordNotFound = errors.New("tagged record not found")
+)
+
+// CRMTagTarget is the kind of record a tag is applied to
+type CRMTagTarget string
+
+const (
+	CRMTagTargetLeads    CRMTagTarget = "leads"
+	CRMTagTargetContacts CRMTagTarget = "contacts"
+)
+
+// CRMTag is an organization tag applied to leads through leads.tag_ids and
+// to contacts through contacts.tags
+type CRMTag struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	Name           string    `json:"name" db:"name"`
+	Color          int       `json:"color" db:"color"`
+	// LeadCount and ContactCount are the live leads and contacts tagged
+	LeadCount    int       `json:"lead_count" db:"lead_count"`
+	ContactCount int       `json:"contact_count" db:"contact_count"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CRMTagFilter represents filtering criteria for tags
+type CRMTagFilter struct {
+	OrganizationID uuid.UUID
+	// Name matches tags whose name contains it, ignoring case
+	Name   *string
+	Limit  int
+	Offset int
+}
+
+// CRMTagCreateRequest represents a request to create a tag
+type CRMTagCreateRequest struct {
+	Name  string `json:"name"`
+	Color int    `json:"color"`
+}
+
+// CRMTagUpdateRequest represents a request to update a tag
+type CRMTagUpdateRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Color *int    `json:"color,omitempty"`
+}
+
+// CRMTagAssignment is the tags to add to a lead or contact, and the tags it
+// carries afterwards
+type CRMTagAssignment struct {
+	TagIDs []uuid.UUID `json:"tag_ids"`
+}
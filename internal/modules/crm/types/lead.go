@@ -1,8 +1,15 @@
 package types
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/computed"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
 	"github.com/google/uuid"
 )
 
@@ -14,6 +21,12 @@ const (
 	LeadTypeOpportunity LeadType = "opportunity"
 )
 
+// LeadTypeEnum lists the lead types to clients
+var LeadTypeEnum = enums.Enum{Name: "crm.lead_type", Values: []enums.Value{
+	{Value: string(LeadTypeLead), Labels: enums.Labels{"en": "Lead", "fr": "Piste", "es": "Prospecto"}},
+	{Value: string(LeadTypeOpportunity), Labels: enums.Labels{"en": "Opportunity", "fr": "Opportunité", "es": "Oportunidad"}},
+}}
+
 // LeadPriority represents the priority of a lead
 type LeadPriority string
 
@@ -24,6 +37,19 @@ const (
 	LeadPriorityUrgent LeadPriority = "urgent"
 )
 
+// LeadPriorityEnum lists the lead priorities to clients
+var LeadPriorityEnum = enums.Enum{Name: "crm.lead_priority", Values: []enums.Value{
+	{Value: string(LeadPriorityLow), Labels: enums.Labels{"en": "Low", "fr": "Basse", "es": "Baja"}},
+	{Value: string(LeadPriorityMedium), Labels: enums.Labels{"en": "Medium", "fr": "Moyenne", "es": "Media"}},
+	{Value: string(LeadPriorityHigh), Labels: enums.Labels{"en": "High", "fr": "Haute", "es": "Alta"}},
+	{Value: string(LeadPriorityUrgent), Labels: enums.Labels{"en": "Urgent", "fr": "Urgente", "es": "Urgente"}},
+}}
+
+// IsValid reports whether the priority is a known priority
+func (p LeadPriority) IsValid() bool {
+	return LeadPriorityEnum.Has(string(p))
+}
+
 // LeadWonStatus represents the won status of a lead
 type LeadWonStatus string
 
@@ -33,6 +59,98 @@ const (
 	LeadWonStatusOngoing LeadWonStatus = "ongoing"
 )
 
+// LeadWonStatusEnum lists the lead won statuses to clients
+var LeadWonStatusEnum = enums.Enum{Name: "crm.lead_won_status", Values: []enums.Value{
+	{Value: string(LeadWonStatusWon), Labels: enums.Labels{"en": "Won", "fr": "Gagné", "es": "Ganado"}},
+	{Value: string(LeadWonStatusLost), Labels: enums.Labels{"en": "Lost", "fr": "Perdu", "es": "Perdido"}},
+	{Value: string(LeadWonStatusOngoing), Labels: enums.Labels{"en": "Ongoing", "fr": "En cours", "es": "En curso"}},
+}}
+
+// LeadStatus represents the lifecycle status of a lead
+type LeadStatus string
+
+const (
+	LeadStatusNew        LeadStatus = "new"
+	LeadStatusInProgress LeadStatus = "in_progress"
+	LeadStatusWon        LeadStatus = "won"
+	LeadStatusLost       LeadStatus = "lost"
+	LeadStatusArchived   LeadStatus = "archived"
+)
+
+// LeadStatusEnum lists the lead lifecycle statuses to clients
+var LeadStatusEnum = enums.Enum{Name: "crm.lead_status", Values: []enums.Value{
+	{Value: string(LeadStatusNew), Labels: enums.Labels{"en": "New", "fr": "Nouveau", "es": "Nuevo"}},
+	{Value: string(LeadStatusInProgress), Labels: enums.Labels{"en": "In progress", "fr": "En cours", "es": "En curso"}},
+	{Value: string(LeadStatusWon), Labels: enums.Labels{"en": "Won", "fr": "Gagné", "es": "Ganado"}},
+	{Value: string(LeadStatusLost), Labels: enums.Labels{"en": "Lost", "fr": "Perdu", "es": "Perdido"}},
+	{Value: string(LeadStatusArchived), Labels: enums.Labels{"en": "Archived", "fr": "Archivé", "es": "Archivado"}},
+}}
+
+// IsValid reports whether the status is a known lifecycle status
+func (s LeadStatus) IsValid() bool {
+	return LeadStatusEnum.Has(string(s))
+}
+
+// IsOpen reports whether the lead is still being worked
+func (s LeadStatus) IsOpen() bool {
+	return s == LeadStatusNew || s == LeadStatusInProgress
+}
+
+// ErrInvalidLeadSort is returned when leads are listed by an unknown field
+// or direction
+var ErrInvalidLeadSort = errors.New("invalid lead sort")
+
+// ErrLeadVersionConflict is returned when a lead is updated against a
+// version other than the stored one
+var ErrLeadVersionConflict = errors.New("lead was changed by another update")
+
+// LeadVersionConflictError carries the stored lead an update conflicted
+// with, so the client can merge its changes into it and retry
+type LeadVersionConflictError struct {
+	Current *Lead
+}
+
+func (e *LeadVersionConflictError) Error() string {
+	return fmt.Sprintf("%s: version is now %d", ErrLeadVersionConflict, e.Current.Version)
+}
+
+func (e *LeadVersionConflictError) Unwrap() error {
+	return ErrLeadVersionConflict
+}
+
+// LeadSortField is a column leads may be listed by
+type LeadSortField string
+
+const (
+	LeadSortCreatedAt       LeadSortField = "created_at"
+	LeadSortExpectedRevenue LeadSortField = "expected_revenue"
+	LeadSortProbability     LeadSortField = "probability"
+	LeadSortDateDeadline    LeadSortField = "date_deadline"
+	LeadSortPriority        LeadSortField = "priority"
+)
+
+// IsValid reports whether leads may be sorted by the field
+func (f LeadSortField) IsValid() bool {
+	switch f {
+	case LeadSortCreatedAt, LeadSortExpectedRevenue, LeadSortProbability, LeadSortDateDeadline, LeadSortPriority:
+		return true
+	}
+	return false
+}
+
+// SortDirection is the direction of a sort
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// IsValid reports whether the direction is asc or desc
+func (d SortDirection) IsValid() bool {
+	return d == SortAsc || d == SortDesc
+}
+
 // Lead represents a comprehensive sales lead with all database fields
 type Lead struct {
 	ID                  uuid.UUID      `json:"id" db:"id"`
@@ -61,7 +179,7 @@ type Lead struct {
 	DateDeadline        *time.Time     `json:"date_deadline,omitempty" db:"date_deadline"`
 	DateLastStageUpdate *time.Time     `json:"date_last_stage_update,omitempty" db:"date_last_stage_update"`
 	Active              bool           `json:"active" db:"active"`
-	Status              *string        `json:"status,omitempty" db:"status"`
+	Status              LeadStatus     `json:"status" db:"status"`
 	AssignedTo          *uuid.UUID     `json:"assigned_to,omitempty" db:"assigned_to"`
 	WonStatus           *LeadWonStatus `json:"won_status,omitempty" db:"won_status"`
 	LostReasonID        *uuid.UUID     `json:"lost_reason_id,omitempty" db:"lost_reason_id"`
@@ -82,6 +200,17 @@ type Lead struct {
 	DeletedAt           *time.Time     `json:"deleted_at,omitempty" db:"deleted_at"`
 	CustomFields        interface{}    `json:"custom_fields,omitempty" db:"custom_fields"`
 	Metadata            interface{}    `json:"metadata,omitempty" db:"metadata"`
+	// SystemProbability is the default of the stage the lead last moved to
+	// and ManualProbability the one set by a user, which Probability follows
+	// while ProbabilityOverridden is set
+	SystemProbability     *int `json:"system_probability,omitempty" db:"system_probability"`
+	ManualProbability     *int `json:"manual_probability,omitempty" db:"manual_probability"`
+	ProbabilityOverridden bool `json:"probability_overridden" db:"probability_overridden"`
+	// Version counts the writes to the lead. An update made against an older
+	// version is refused rather than overwriting the writes it did not see.
+	Version int `json:"version" db:"version"`
+	// Computed holds the organization's computed field values, keyed by field name
+	Computed map[string]interface{} `json:"computed,omitempty" db:"-"`
 }
 
 // LeadFilter represents filtering criteria for enhanced leads
@@ -109,7 +238,7 @@ type LeadFilter struct {
 	WonStatus          *LeadWonStatus
 	LostReasonID       *uuid.UUID
 	Active             *bool
-	Status             *string
+	Status             *LeadStatus
 	AssignedTo         *uuid.UUID
 	DateOpenFrom       *time.Time
 	DateOpenTo         *time.Time
@@ -121,6 +250,52 @@ type LeadFilter struct {
 	CreatedBy          *uuid.UUID
 	UpdatedBy          *uuid.UUID
 	Color              *string
-	Limit              int
-	Offset             int
+	MatchMode          database.MatchMode
+	// TagIDs matches leads carrying any of the tags
+	TagIDs []uuid.UUID
+	// ComputedFields are selected alongside each lead and may be filtered on
+	ComputedFields []computed.Field
+	// ComputedMin and ComputedMax bound computed field values by field name
+	ComputedMin map[string]float64
+	ComputedMax map[string]float64
+	// CustomFieldFilters are raw equality filters on custom fields by field
+	// name; the service checks them against the definitions and sets
+	// CustomFieldsContain to the JSON document matching leads contain
+	CustomFieldFilters  map[string]string
+	CustomFieldsContain string
+	// Query is a boolean filter tree over the lead's fields; the service
+	// compiles it against the custom field definitions to Condition
+	Query     *query.Node
+	Condition *query.Condition
+	// SortBy orders the listed leads, by name when empty. SortDir defaults
+	// to ascending.
+	SortBy  LeadSortField
+	SortDir SortDirection
+	Limit   int
+	Offset  int
+}
+
+// LeadPage is a page of leads with the total matching the filter across all
+// pages. Total is null when lead list totals are turned off.
+type LeadPage struct {
+	Data   []*Lead `json:"data"`
+	Total  *int    `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// ValidateSort checks SortBy and SortDir against the sortable fields
+func (f LeadFilter) ValidateSort() error {
+	if f.SortBy != "" && !f.SortBy.IsValid() {
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidLeadSort, f.SortBy)
+	}
+	if f.SortDir != "" {
+		if !f.SortDir.IsValid() {
+			return fmt.Errorf("%w: sort direction must be asc or desc", ErrInvalidLeadSort)
+		}
+		if f.SortBy == "" {
+			return fmt.Errorf("%w: sort direction requires a sort field", ErrInvalidLeadSort)
+		}
+	}
+	return nil
 }
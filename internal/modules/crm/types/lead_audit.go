@@ -0,0 +1,81 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/audit"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLeadAuditFilter is returned for audit exports that fail validation
+var ErrInvalidLeadAuditFilter = errors.New("invalid lead audit filter")
+
+// MaxLeadAuditExport is the most entries one audit export returns
+const MaxLeadAuditExport = 50000
+
+// LeadAuditSource is the kind of update that changed a lead
+type LeadAuditSource string
+
+const (
+	LeadAuditUpdate     LeadAuditSource = "update"
+	LeadAuditBulkUpdate LeadAuditSource = "bulk_update"
+	LeadAuditRestore    LeadAuditSource = "restore"
+)
+
+// LeadAuditEntry is one field changed by a lead update, with its values as
+// JSON before and after
+type LeadAuditEntry struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id" db:"organization_id"`
+	LeadID         uuid.UUID       `json:"lead_id" db:"lead_id"`
+	Field          string          `json:"field" db:"field"`
+	OldValue       json.RawMessage `json:"old_value" db:"old_value"`
+	NewValue       json.RawMessage `json:"new_value" db:"new_value"`
+	Source         LeadAuditSource `json:"source" db:"source"`
+	ChangedBy      *uuid.UUID      `json:"changed_by,omitempty" db:"changed_by"`
+	ChangedAt      time.Time       `json:"changed_at" db:"changed_at"`
+}
+
+// NewLeadAuditEntries turns the field changes of one lead update into audit
+// entries, encoding each value as JSON
+func NewLeadAuditEntries(orgID, leadID uuid.UUID, changes []audit.FieldChange, source LeadAuditSource, changedBy *uuid.UUID, changedAt time.Time) ([]LeadAuditEntry, error) {
+	entries := make([]LeadAuditEntry, 0, len(changes))
+	for _, change := range changes {
+		oldValue, err := json.Marshal(change.Old)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode old %s: %w", change.Field, err)
+		}
+		newValue, err := json.Marshal(change.New)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode new %s: %w", change.Field, err)
+		}
+		entries = append(entries, LeadAuditEntry{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			LeadID:         leadID,
+			Field:          change.Field,
+			OldValue:       oldValue,
+			NewValue:       newValue,
+			Source:         source,
+			ChangedBy:      changedBy,
+			ChangedAt:      changedAt,
+		})
+	}
+	return entries, nil
+}
+
+// LeadAuditFilter selects the organization's audit entries for export,
+// oldest first. From is inclusive and To exclusive.
+type LeadAuditFilter struct {
+	OrganizationID uuid.UUID
+	From           time.Time
+	To             time.Time
+	LeadID         *uuid.UUID
+	Field          *string
+	ChangedBy      *uuid.UUID
+	Limit          int
+}
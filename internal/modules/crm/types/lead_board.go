@@ -0,0 +1,51 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLeadBoard wraps validation failures of lead board requests
+var ErrInvalidLeadBoard = errors.New("invalid lead board")
+
+// LeadBoardRequest asks for the organization's leads grouped by stage.
+// Filter narrows the leads of every column and orders the cards within
+// them; Limit caps the cards of each column. Cursors are the next_cursor of
+// columns already loaded, each loading that column's next cards.
+type LeadBoardRequest struct {
+	Filter  LeadFilter
+	Limit   int
+	Cursors []string
+}
+
+// LeadBoardTotals counts the leads of one stage and sums their expected
+// revenue. StageID is nil for leads without a stage.
+type LeadBoardTotals struct {
+	StageID         *uuid.UUID
+	Count           int
+	ExpectedRevenue float64
+}
+
+// LeadBoardColumn is one stage of the board with the totals of all its
+// leads and a page of its cards. NextCursor is set while more cards remain.
+// The column of leads without a stage has no stage ID.
+type LeadBoardColumn struct {
+	StageID         *uuid.UUID `json:"stage_id"`
+	StageName       string     `json:"stage_name"`
+	Sequence        int        `json:"sequence"`
+	Fold            bool       `json:"fold"`
+	IsWon           bool       `json:"is_won"`
+	Count           int        `json:"count"`
+	ExpectedRevenue float64    `json:"expected_revenue"`
+	Leads           []*Lead    `json:"leads"`
+	NextCursor      string     `json:"next_cursor,omitempty"`
+}
+
+// LeadBoard is the kanban view of an organization's leads, one column per
+// stage in pipeline order. Total and ExpectedRevenue add up every column.
+type LeadBoard struct {
+	Columns         []LeadBoardColumn `json:"columns"`
+	Total           int               `json:"total"`
+	ExpectedRevenue float64           `json:"expected_revenue"`
+}
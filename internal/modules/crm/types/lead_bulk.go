@@ -0,0 +1,139 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLeadBulkRequest wraps validation failures of bulk lead requests
+var ErrInvalidLeadBulkRequest = errors.New("invalid bulk lead request")
+
+// MaxLeadBulkSize is the most leads one bulk request may change
+const MaxLeadBulkSize = 5000
+
+// LeadBulkFilter selects the leads of a bulk request by their fields
+type LeadBulkFilter struct {
+	StageID    *uuid.UUID    `json:"stage_id,omitempty"`
+	TeamID     *uuid.UUID    `json:"team_id,omitempty"`
+	UserID     *uuid.UUID    `json:"user_id,omitempty"`
+	AssignedTo *uuid.UUID    `json:"assigned_to,omitempty"`
+	SourceID   *uuid.UUID    `json:"source_id,omitempty"`
+	CampaignID *uuid.UUID    `json:"campaign_id,omitempty"`
+	LeadType   *LeadType     `json:"lead_type,omitempty"`
+	Priority   *LeadPriority `json:"priority,omitempty"`
+	Status     *LeadStatus   `json:"status,omitempty"`
+	Active     *bool         `json:"active,omitempty"`
+}
+
+// IsEmpty reports whether the filter sets no criteria, which would select
+// every lead of the organization
+func (f LeadBulkFilter) IsEmpty() bool {
+	return f == LeadBulkFilter{}
+}
+
+// LeadFilter returns the lead filter selecting the same leads
+func (f LeadBulkFilter) LeadFilter(orgID uuid.UUID) LeadFilter {
+	return LeadFilter{
+		OrganizationID: orgID,
+		StageID:        f.StageID,
+		TeamID:         f.TeamID,
+		UserID:         f.UserID,
+		AssignedTo:     f.AssignedTo,
+		SourceID:       f.SourceID,
+		CampaignID:     f.CampaignID,
+		LeadType:       f.LeadType,
+		Priority:       f.Priority,
+		Status:         f.Status,
+		Active:         f.Active,
+	}
+}
+
+// LeadBulkChanges are the field changes applied to every selected lead.
+// Omitted fields are left unchanged.
+type LeadBulkChanges struct {
+	// StageID moves the leads, subject to their pipeline's transitions
+	StageID    *uuid.UUID    `json:"stage_id,omitempty"`
+	AssignedTo *uuid.UUID    `json:"assigned_to,omitempty"`
+	Priority   *LeadPriority `json:"priority,omitempty"`
+	// AddTagIDs and RemoveTagIDs edit the leads' tags, keeping the others
+	AddTagIDs    []uuid.UUID `json:"add_tag_ids,omitempty"`
+	RemoveTagIDs []uuid.UUID `json:"remove_tag_ids,omitempty"`
+}
+
+// IsEmpty reports whether the changes change nothing
+func (c LeadBulkChanges) IsEmpty() bool {
+	return c.StageID == nil && c.AssignedTo == nil && c.Priority == nil &&
+		len(c.AddTagIDs) == 0 && len(c.RemoveTagIDs) == 0
+}
+
+// LeadBulkUpdateRequest changes the leads listed in IDs or, when IDs is
+// empty, the leads matching Filter. With AllOrNothing, a lead that cannot be
+// changed leaves every lead unchanged; otherwise it is skipped.
+type LeadBulkUpdateRequest struct {
+	IDs          []uuid.UUID     `json:"ids,omitempty"`
+	Filter       *LeadBulkFilter `json:"filter,omitempty"`
+	Changes      LeadBulkChanges `json:"changes"`
+	AllOrNothing bool            `json:"all_or_nothing,omitempty"`
+}
+
+// LeadBulkDeleteRequest deletes the leads listed in IDs or, when IDs is
+// empty, the leads matching Filter
+type LeadBulkDeleteRequest struct {
+	IDs    []uuid.UUID     `json:"ids,omitempty"`
+	Filter *LeadBulkFilter `json:"filter,omitempty"`
+}
+
+// LeadBulkTarget is a lead selected by a bulk request, with the fields its
+// changes are checked against
+type LeadBulkTarget struct {
+	ID      uuid.UUID  `json:"id" db:"id"`
+	TeamID  *uuid.UUID `json:"team_id,omitempty" db:"team_id"`
+	StageID *uuid.UUID `json:"stage_id,omitempty" db:"stage_id"`
+}
+
+// LeadBulkBatch is a checked bulk update applied in one transaction
+type LeadBulkBatch struct {
+	OrganizationID uuid.UUID
+	LeadIDs        []uuid.UUID
+	Changes        LeadBulkChanges
+	// FromStageIDs holds the stage each lead was checked in when the changes
+	// move leads; a lead that moved since fails
+	FromStageIDs map[uuid.UUID]*uuid.UUID
+	// Probability is the default probability of the stage the leads move
+	// to, applied to the leads whose probability was not overridden
+	Probability  *int
+	AllOrNothing bool
+	ChangedBy    *uuid.UUID
+	ChangedAt    time.Time
+}
+
+// LeadBulkStatus is what a bulk request did to one lead
+type LeadBulkStatus string
+
+const (
+	LeadBulkStatusUpdated LeadBulkStatus = "updated"
+	LeadBulkStatusDeleted LeadBulkStatus = "deleted"
+	// LeadBulkStatusFailed leads could not be changed, as Error explains
+	LeadBulkStatusFailed LeadBulkStatus = "failed"
+	// LeadBulkStatusSkipped leads were left unchanged because another lead
+	// of an all-or-nothing request failed
+	LeadBulkStatusSkipped LeadBulkStatus = "skipped"
+)
+
+// LeadBulkItemResult is the outcome of a bulk request for one lead
+type LeadBulkItemResult struct {
+	LeadID uuid.UUID      `json:"lead_id"`
+	Status LeadBulkStatus `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// LeadBulkResult is the outcome of a bulk request, with one result per
+// selected lead in request order, or name order for a filter
+type LeadBulkResult struct {
+	Matched   int                  `json:"matched"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Results   []LeadBulkItemResult `json:"results"`
+}
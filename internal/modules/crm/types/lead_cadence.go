@@ -0,0 +1,142 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidLeadCadence wraps validation failures of cadences
+	ErrInvalidLeadCadence = errors.New("invalid lead cadence")
+	// ErrLeadCadenceExists is returned when the organization already has a
+	// cadence for the same source and stage
+	ErrLeadCadenceExists = errors.New("a lead cadence already exists for this source and stage")
+	// ErrLeadCadenceClosed is returned when skipping a task or stopping an
+	// enrollment that is no longer pending or active
+	ErrLeadCadenceClosed = errors.New("lead cadence task or enrollment is already closed")
+)
+
+// LeadCadence is a sequence of follow-up activities created for the leads
+// entering its scope. A nil SourceID or StageID matches every source or
+// stage.
+type LeadCadence struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id" db:"organization_id"`
+	Name           string            `json:"name" db:"name"`
+	SourceID       *uuid.UUID        `json:"source_id,omitempty" db:"source_id"`
+	StageID        *uuid.UUID        `json:"stage_id,omitempty" db:"stage_id"`
+	Active         bool              `json:"active" db:"active"`
+	Steps          []LeadCadenceStep `json:"steps" db:"-"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+	CreatedBy      *uuid.UUID        `json:"created_by,omitempty" db:"created_by"`
+	UpdatedBy      *uuid.UUID        `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// LeadCadenceStep is an activity template of a cadence, due OffsetMinutes
+// after the lead entered the cadence's scope
+type LeadCadenceStep struct {
+	Sequence      int          `json:"sequence" db:"sequence"`
+	OffsetMinutes int          `json:"offset_minutes" db:"offset_minutes"`
+	ActivityType  ActivityType `json:"activity_type" db:"activity_type"`
+	Summary       string       `json:"summary" db:"summary"`
+	Note          *string      `json:"note,omitempty" db:"note"`
+}
+
+// LeadCadenceRequest represents a request to create or update a cadence.
+// Steps are numbered in the order given.
+type LeadCadenceRequest struct {
+	Name     string            `json:"name"`
+	SourceID *uuid.UUID        `json:"source_id,omitempty"`
+	StageID  *uuid.UUID        `json:"stage_id,omitempty"`
+	Active   *bool             `json:"active,omitempty"`
+	Steps    []LeadCadenceStep `json:"steps"`
+}
+
+// LeadCadenceEnrollmentStatus is where a lead is in a cadence
+type LeadCadenceEnrollmentStatus string
+
+const (
+	LeadCadenceEnrollmentActive    LeadCadenceEnrollmentStatus = "active"
+	LeadCadenceEnrollmentCompleted LeadCadenceEnrollmentStatus = "completed"
+	// LeadCadenceEnrollmentStopped is a lead that left the cadence's scope,
+	// closed or was stopped by hand with tasks still pending
+	LeadCadenceEnrollmentStopped LeadCadenceEnrollmentStatus = "stopped"
+)
+
+// LeadCadenceTaskStatus is what became of a cadence step for a lead
+type LeadCadenceTaskStatus string
+
+const (
+	LeadCadenceTaskPending LeadCadenceTaskStatus = "pending"
+	LeadCadenceTaskDone    LeadCadenceTaskStatus = "done"
+	LeadCadenceTaskSkipped LeadCadenceTaskStatus = "skipped"
+)
+
+// LeadCadenceEnrollment is a lead going through a cadence after entering
+// its scope
+type LeadCadenceEnrollment struct {
+	ID             uuid.UUID                   `json:"id" db:"id"`
+	OrganizationID uuid.UUID                   `json:"organization_id" db:"organization_id"`
+	LeadID         uuid.UUID                   `json:"lead_id" db:"lead_id"`
+	CadenceID      *uuid.UUID                  `json:"cadence_id,omitempty" db:"cadence_id"`
+	CadenceName    string                      `json:"cadence_name" db:"cadence_name"`
+	SourceID       *uuid.UUID                  `json:"source_id,omitempty" db:"source_id"`
+	StageID        *uuid.UUID                  `json:"stage_id,omitempty" db:"stage_id"`
+	AssignedTo     *uuid.UUID                  `json:"assigned_to,omitempty" db:"assigned_to"`
+	EnteredAt      time.Time                   `json:"entered_at" db:"entered_at"`
+	Status         LeadCadenceEnrollmentStatus `json:"status" db:"status"`
+	FinishedAt     *time.Time                  `json:"finished_at,omitempty" db:"finished_at"`
+	Tasks          []LeadCadenceTask           `json:"tasks" db:"-"`
+}
+
+// LeadCadenceTask is a cadence step of an enrollment and the activity it
+// created on the lead
+type LeadCadenceTask struct {
+	ID           uuid.UUID             `json:"id" db:"id"`
+	EnrollmentID uuid.UUID             `json:"enrollment_id" db:"enrollment_id"`
+	Sequence     int                   `json:"sequence" db:"sequence"`
+	ActivityID   *uuid.UUID            `json:"activity_id,omitempty" db:"activity_id"`
+	ActivityType ActivityType          `json:"activity_type" db:"activity_type"`
+	Summary      string                `json:"summary" db:"summary"`
+	Note         *string               `json:"note,omitempty" db:"-"`
+	DueAt        time.Time             `json:"due_at" db:"due_at"`
+	Status       LeadCadenceTaskStatus `json:"status" db:"status"`
+	CompletedAt  *time.Time            `json:"completed_at,omitempty" db:"completed_at"`
+	SkippedBy    *uuid.UUID            `json:"skipped_by,omitempty" db:"skipped_by"`
+}
+
+// LeadCadenceEntry is an open lead's entry into the scope of the most
+// specific active cadence that has no enrollment yet
+type LeadCadenceEntry struct {
+	LeadID         uuid.UUID
+	OrganizationID uuid.UUID
+	CadenceID      uuid.UUID
+	AssignedTo     *uuid.UUID
+	EnteredAt      time.Time
+}
+
+// LeadCadencePerformance counts how the enrollments of a cadence went
+type LeadCadencePerformance struct {
+	CadenceID    uuid.UUID `json:"cadence_id"`
+	Name         string    `json:"name"`
+	Enrolled     int       `json:"enrolled"`
+	Active       int       `json:"active"`
+	Completed    int       `json:"completed"`
+	Stopped      int       `json:"stopped"`
+	TasksDone    int       `json:"tasks_done"`
+	TasksSkipped int       `json:"tasks_skipped"`
+	TasksPending int       `json:"tasks_pending"`
+	// CompletionRate is the percentage of finished enrollments that
+	// completed rather than stopped
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// LeadCadenceRun summarizes one pass of the cadence worker
+type LeadCadenceRun struct {
+	Enrolled  int `json:"enrolled"`
+	Completed int `json:"completed"`
+	Stopped   int `json:"stopped"`
+}
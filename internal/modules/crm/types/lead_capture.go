@@ -0,0 +1,108 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// LeadMergeStrategy controls what a capture form does with a submission that
+// matches an open lead inside the dedup window
+type LeadMergeStrategy string
+
+const (
+	// LeadMergeStrategyCreateNew always creates a new lead
+	LeadMergeStrategyCreateNew LeadMergeStrategy = "create_new"
+	// LeadMergeStrategyUpdateExisting overwrites the open lead with the submitted values
+	LeadMergeStrategyUpdateExisting LeadMergeStrategy = "update_existing"
+	// LeadMergeStrategyKeepExisting returns the open lead unchanged
+	LeadMergeStrategyKeepExisting LeadMergeStrategy = "keep_existing"
+)
+
+// IsValid reports whether the merge strategy is known
+func (s LeadMergeStrategy) IsValid() bool {
+	switch s {
+	case LeadMergeStrategyCreateNew, LeadMergeStrategyUpdateExisting, LeadMergeStrategyKeepExisting:
+		return true
+	}
+	return false
+}
+
+const (
+	// DefaultDedupWindowMinutes is the dedup window applied when a form does not set one
+	DefaultDedupWindowMinutes = 24 * 60
+	// MaxDedupWindowMinutes caps the dedup window at 30 days
+	MaxDedupWindowMinutes = 30 * 24 * 60
+)
+
+// LeadCaptureForm configures how submissions from one web form become leads
+type LeadCaptureForm struct {
+	ID                 uuid.UUID         `json:"id" db:"id"`
+	OrganizationID     uuid.UUID         `json:"organization_id" db:"organization_id"`
+	Name               string            `json:"name" db:"name"`
+	SourceID           *uuid.UUID        `json:"source_id,omitempty" db:"source_id"`
+	TeamID             *uuid.UUID        `json:"team_id,omitempty" db:"team_id"`
+	DedupWindowMinutes int               `json:"dedup_window_minutes" db:"dedup_window_minutes"`
+	MergeStrategy      LeadMergeStrategy `json:"merge_strategy" db:"merge_strategy"`
+	Active             bool              `json:"active" db:"active"`
+	CreatedAt          time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// DedupWindow returns the dedup window as a duration
+func (f LeadCaptureForm) DedupWindow() time.Duration {
+	return time.Duration(f.DedupWindowMinutes) * time.Minute
+}
+
+// LeadCaptureFormFilter represents filtering criteria for capture forms
+type LeadCaptureFormFilter struct {
+	OrganizationID uuid.UUID
+	Name           *string
+	MatchMode      database.MatchMode
+	Active         *bool
+	Limit          int
+	Offset         int
+}
+
+// LeadCaptureFormCreateRequest represents a request to create a capture form
+type LeadCaptureFormCreateRequest struct {
+	Name               string            `json:"name"`
+	SourceID           *uuid.UUID        `json:"source_id,omitempty"`
+	TeamID             *uuid.UUID        `json:"team_id,omitempty"`
+	DedupWindowMinutes *int              `json:"dedup_window_minutes,omitempty"`
+	MergeStrategy      LeadMergeStrategy `json:"merge_strategy,omitempty"`
+	Active             *bool             `json:"active,omitempty"`
+}
+
+// LeadCaptureFormUpdateRequest represents a request to update a capture form
+type LeadCaptureFormUpdateRequest struct {
+	Name               *string            `json:"name,omitempty"`
+	SourceID           *uuid.UUID         `json:"source_id,omitempty"`
+	TeamID             *uuid.UUID         `json:"team_id,omitempty"`
+	DedupWindowMinutes *int               `json:"dedup_window_minutes,omitempty"`
+	MergeStrategy      *LeadMergeStrategy `json:"merge_strategy,omitempty"`
+	Active             *bool              `json:"active,omitempty"`
+}
+
+// LeadCaptureOutcome describes what a capture submission did
+type LeadCaptureOutcome string
+
+const (
+	LeadCaptureOutcomeCreated   LeadCaptureOutcome = "created"
+	LeadCaptureOutcomeMerged    LeadCaptureOutcome = "merged"
+	LeadCaptureOutcomeDuplicate LeadCaptureOutcome = "duplicate"
+)
+
+// LeadCaptureResult is the response to a capture form submission
+type LeadCaptureResult struct {
+	Lead          Lead               `json:"lead"`
+	Outcome       LeadCaptureOutcome `json:"outcome"`
+	FormID        uuid.UUID          `json:"form_id"`
+	MergeStrategy LeadMergeStrategy  `json:"merge_strategy"`
+	// MatchedLeadID is set when the submission matched an open lead in the dedup window
+	MatchedLeadID *uuid.UUID `json:"matched_lead_id,omitempty"`
+	// MergedFields lists the lead fields overwritten by an update_existing merge
+	MergedFields []string `json:"merged_fields,omitempty"`
+}
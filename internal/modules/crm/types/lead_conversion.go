@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLeadNotConvertible is returned when converting a lead that is
+	// already an opportunity or is no longer open
+	ErrLeadNotConvertible = errors.New("lead cannot be converted")
+	// ErrInvalidLeadConversion wraps validation failures of conversion requests
+	ErrInvalidLeadConversion = errors.New("invalid lead conversion")
+)
+
+// LeadConvertRequest represents a request to convert a lead to an opportunity.
+// The contact is, in order of preference, ContactID, the contact the lead
+// is already linked to, an existing contact with the lead's email when
+// MatchExisting is set, or a new contact created from the lead.
+type LeadConvertRequest struct {
+	ContactID       *uuid.UUID            `json:"contact_id,omitempty"`
+	MatchExisting   bool                  `json:"match_existing"`
+	OpportunityName *string               `json:"opportunity_name,omitempty"`
+	ExpectedRevenue *float64              `json:"expected_revenue,omitempty"`
+	DateDeadline    *time.Time            `json:"date_deadline,omitempty"`
+	AssignedTo      *uuid.UUID            `json:"assigned_to,omitempty"`
+	Quotation       *LeadQuotationRequest `json:"quotation,omitempty"`
+}
+
+// LeadQuotationRequest represents the draft quotation raised on conversion
+type LeadQuotationRequest struct {
+	ValidityDate *time.Time          `json:"validity_date,omitempty"`
+	Note         *string             `json:"note,omitempty"`
+	Lines        []LeadQuotationLine `json:"lines,omitempty"`
+}
+
+// LeadQuotationLine represents a line of the quotation raised on conversion
+type LeadQuotationLine struct {
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Name      string     `json:"name"`
+	Quantity  float64    `json:"quantity"`
+	PriceUnit float64    `json:"price_unit"`
+}
+
+// LeadConversion is the outcome of converting a lead. The lead keeps its ID
+// as the opportunity, so Activities and Attachments count what stays
+// attached to it.
+type LeadConversion struct {
+	LeadID          uuid.UUID  `json:"lead_id"`
+	OrganizationID  uuid.UUID  `json:"organization_id"`
+	OpportunityName string     `json:"opportunity_name"`
+	ContactID       uuid.UUID  `json:"contact_id"`
+	ContactCreated  bool       `json:"contact_created"`
+	QuotationID     *uuid.UUID `json:"quotation_id,omitempty"`
+	QuotationName   *string    `json:"quotation_name,omitempty"`
+	Activities      int        `json:"activities"`
+	Attachments     int        `json:"attachments"`
+	ConvertedAt     time.Time  `json:"converted_at"`
+	ConvertedBy     *uuid.UUID `json:"converted_by,omitempty"`
+}
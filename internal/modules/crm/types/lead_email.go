@@ -0,0 +1,113 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/email/inbound"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidLeadEmailInbox is returned for inbox requests that fail validation
+	ErrInvalidLeadEmailInbox = errors.New("invalid lead email inbox")
+	// ErrLeadEmailInboxNotFound is returned for unknown or inactive webhook tokens
+	ErrLeadEmailInboxNotFound = errors.New("lead email inbox not found")
+	// ErrLeadEmailDuplicate is returned when a message was already received
+	ErrLeadEmailDuplicate = errors.New("lead email already received")
+)
+
+// LeadEmailInbox is an address whose received emails become leads. Its
+// provider posts them to the webhook URL carrying Token.
+type LeadEmailInbox struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id" db:"organization_id"`
+	Name           string           `json:"name" db:"name"`
+	Address        *string          `json:"address,omitempty" db:"address"`
+	Provider       inbound.Provider `json:"provider" db:"provider"`
+	Token          string           `json:"token" db:"token"`
+	// SigningKey verifies Mailgun posts; it is never returned
+	SigningKey *string    `json:"-" db:"signing_key"`
+	HasSigning bool       `json:"has_signing_key" db:"-"`
+	SourceID   *uuid.UUID `json:"source_id,omitempty" db:"source_id"`
+	TeamID     *uuid.UUID `json:"team_id,omitempty" db:"team_id"`
+	Active     bool       `json:"active" db:"active"`
+	// WebhookPath is where the provider posts, relative to the API host
+	WebhookPath string     `json:"webhook_path" db:"-"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// LeadEmailInboxRequest creates or updates an inbox. On update, omitted
+// fields are left unchanged and an empty signing key removes it.
+type LeadEmailInboxRequest struct {
+	Name       *string           `json:"name,omitempty"`
+	Address    *string           `json:"address,omitempty"`
+	Provider   *inbound.Provider `json:"provider,omitempty"`
+	SigningKey *string           `json:"signing_key,omitempty"`
+	SourceID   *uuid.UUID        `json:"source_id,omitempty"`
+	TeamID     *uuid.UUID        `json:"team_id,omitempty"`
+	Active     *bool             `json:"active,omitempty"`
+	// RotateToken issues a new webhook token, invalidating the old URL
+	RotateToken bool `json:"rotate_token,omitempty"`
+}
+
+// LeadEmailMessage is a received email attached to a lead
+type LeadEmailMessage struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	InboxID        *uuid.UUID `json:"inbox_id,omitempty" db:"inbox_id"`
+	LeadID         uuid.UUID  `json:"lead_id" db:"lead_id"`
+	ActivityID     *uuid.UUID `json:"activity_id,omitempty" db:"activity_id"`
+	// ThreadID is the id of the first message of the thread
+	ThreadID   uuid.UUID `json:"thread_id" db:"thread_id"`
+	MessageID  *string   `json:"message_id,omitempty" db:"message_id"`
+	InReplyTo  *string   `json:"in_reply_to,omitempty" db:"in_reply_to"`
+	References []string  `json:"references,omitempty" db:"references"`
+	FromEmail  string    `json:"from_email" db:"from_email"`
+	FromName   *string   `json:"from_name,omitempty" db:"from_name"`
+	ToEmails   []string  `json:"to_emails,omitempty" db:"to_emails"`
+	Subject    string    `json:"subject" db:"subject"`
+	BodyText   *string   `json:"body_text,omitempty" db:"body_text"`
+	BodyHTML   *string   `json:"body_html,omitempty" db:"body_html"`
+	ReceivedAt time.Time `json:"received_at" db:"received_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// LeadEmailThread is a lead's conversation, oldest message first
+type LeadEmailThread struct {
+	ThreadID      uuid.UUID          `json:"thread_id"`
+	Subject       string             `json:"subject"`
+	MessageCount  int                `json:"message_count"`
+	LastMessageAt time.Time          `json:"last_message_at"`
+	Messages      []LeadEmailMessage `json:"messages"`
+}
+
+// LeadEmailOutcome describes what an inbound email did
+type LeadEmailOutcome string
+
+const (
+	// LeadEmailOutcomeCreated created a lead for the sender
+	LeadEmailOutcomeCreated LeadEmailOutcome = "created"
+	// LeadEmailOutcomeMatched was attached to the sender's open lead
+	LeadEmailOutcomeMatched LeadEmailOutcome = "matched"
+	// LeadEmailOutcomeThreaded was a reply attached to its thread's lead
+	LeadEmailOutcomeThreaded LeadEmailOutcome = "threaded"
+	// LeadEmailOutcomeDuplicate was a provider retry of a received message
+	LeadEmailOutcomeDuplicate LeadEmailOutcome = "duplicate"
+	// LeadEmailOutcomeIgnored was an auto-reply matching no lead
+	LeadEmailOutcomeIgnored LeadEmailOutcome = "ignored"
+	// LeadEmailOutcomeSubscribed confirmed an SES notification subscription
+	LeadEmailOutcomeSubscribed LeadEmailOutcome = "subscribed"
+)
+
+// LeadEmailResult is the response to an inbound email webhook
+type LeadEmailResult struct {
+	Outcome   LeadEmailOutcome `json:"outcome"`
+	LeadID    *uuid.UUID       `json:"lead_id,omitempty"`
+	MessageID *uuid.UUID       `json:"message_id,omitempty"`
+	ThreadID  *uuid.UUID       `json:"thread_id,omitempty"`
+}
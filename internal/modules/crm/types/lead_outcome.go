@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLostReasonRequired is returned when a lead is marked lost without
+	// a lost reason
+	ErrLostReasonRequired = errors.New("a lost reason is required to mark a lead as lost")
+	// ErrLeadClosed is returned when marking a lead won or lost that is no
+	// longer open
+	ErrLeadClosed = errors.New("lead is already closed")
+	// ErrInvalidLeadOutcome wraps validation failures of won and lost requests
+	ErrInvalidLeadOutcome = errors.New("invalid lead outcome")
+)
+
+// LeadMarkLostRequest represents a request to mark a lead as lost
+type LeadMarkLostRequest struct {
+	LostReasonID *uuid.UUID `json:"lost_reason_id"`
+	Note         *string    `json:"note,omitempty"`
+}
+
+// LeadMarkWonRequest represents a request to mark a lead as won. The
+// expected revenue, when set, records the final deal value.
+type LeadMarkWonRequest struct {
+	ExpectedRevenue *float64 `json:"expected_revenue,omitempty"`
+	Note            *string  `json:"note,omitempty"`
+}
+
+// LeadOutcome is the result of closing a lead as won or lost
+type LeadOutcome struct {
+	LeadID          uuid.UUID     `json:"lead_id"`
+	OrganizationID  uuid.UUID     `json:"organization_id"`
+	Name            string        `json:"name"`
+	WonStatus       LeadWonStatus `json:"won_status"`
+	LostReasonID    *uuid.UUID    `json:"lost_reason_id,omitempty"`
+	LostReason      string        `json:"lost_reason,omitempty"`
+	Note            *string       `json:"note,omitempty"`
+	ExpectedRevenue *float64      `json:"expected_revenue,omitempty"`
+	AssignedTo      *uuid.UUID    `json:"assigned_to,omitempty"`
+	TeamID          *uuid.UUID    `json:"team_id,omitempty"`
+	ClosedAt        time.Time     `json:"date_closed"`
+	ClosedBy        *uuid.UUID    `json:"closed_by,omitempty"`
+}
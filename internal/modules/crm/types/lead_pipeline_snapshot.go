@@ -0,0 +1,94 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoPipelineSnapshot is returned when no pipeline snapshot was taken for
+// the week asked for
+var ErrNoPipelineSnapshot = errors.New("no pipeline snapshot for the week")
+
+// PipelineSnapshotWeek returns the Monday 00:00 UTC starting the week of t,
+// the instant pipeline snapshots are taken at
+func PipelineSnapshotWeek(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// LeadPipelineTotals are the open leads of part of a pipeline. The weighted
+// value counts each lead's expected revenue at its probability.
+type LeadPipelineTotals struct {
+	Count         int     `json:"count"`
+	Value         float64 `json:"value"`
+	WeightedValue float64 `json:"weighted_value"`
+}
+
+// LeadPipelineSnapshotTotal is the snapshot of a team's stage in a week
+type LeadPipelineSnapshotTotal struct {
+	TeamID        *uuid.UUID `json:"team_id,omitempty"`
+	TeamName      string     `json:"team_name,omitempty"`
+	StageID       *uuid.UUID `json:"stage_id,omitempty"`
+	StageName     string     `json:"stage_name,omitempty"`
+	StageSequence *int       `json:"stage_sequence,omitempty"`
+	LeadPipelineTotals
+}
+
+// LeadPipelineMovement is how the leads of a team's stage changed between
+// two snapshots. Entered counts the leads in the stage this week that were
+// not last week, EnteredNew those of them that were in no open stage last
+// week. Exited counts the leads in the stage last week that are not this
+// week: Advanced moved to a later stage of the same team, Regressed to an
+// earlier one, and Closed left the open pipeline, being won, lost or
+// deleted. The rest moved to another team.
+type LeadPipelineMovement struct {
+	Entered    int `json:"entered"`
+	EnteredNew int `json:"entered_new"`
+	Exited     int `json:"exited"`
+	Advanced   int `json:"advanced"`
+	Regressed  int `json:"regressed"`
+	Closed     int `json:"closed"`
+}
+
+// LeadPipelineStageMovement is the movement of a team's stage
+type LeadPipelineStageMovement struct {
+	TeamID  *uuid.UUID `json:"team_id,omitempty"`
+	StageID *uuid.UUID `json:"stage_id,omitempty"`
+	LeadPipelineMovement
+}
+
+// LeadPipelineStageComparison compares a team's stage between two weeks
+type LeadPipelineStageComparison struct {
+	TeamID        *uuid.UUID           `json:"team_id,omitempty"`
+	TeamName      string               `json:"team_name,omitempty"`
+	StageID       *uuid.UUID           `json:"stage_id,omitempty"`
+	StageName     string               `json:"stage_name,omitempty"`
+	StageSequence *int                 `json:"stage_sequence,omitempty"`
+	Current       LeadPipelineTotals   `json:"current"`
+	Previous      LeadPipelineTotals   `json:"previous"`
+	Movement      LeadPipelineMovement `json:"movement"`
+}
+
+// LeadPipelineComparison compares the pipeline snapshot of a week with the
+// week before, per team and stage, ordered by team name and stage sequence.
+// Without a snapshot the week before, HasPrevious is false and there is no
+// movement.
+type LeadPipelineComparison struct {
+	WeekStart         time.Time                     `json:"week_start"`
+	PreviousWeekStart time.Time                     `json:"previous_week_start"`
+	TeamID            *uuid.UUID                    `json:"team_id,omitempty"`
+	HasPrevious       bool                          `json:"has_previous"`
+	Current           LeadPipelineTotals            `json:"current"`
+	Previous          LeadPipelineTotals            `json:"previous"`
+	Stages            []LeadPipelineStageComparison `json:"stages"`
+}
+
+// LeadPipelineSnapshotRun reports a pass of the snapshot job
+type LeadPipelineSnapshotRun struct {
+	WeekStart     time.Time `json:"week_start"`
+	Organizations int       `json:"organizations"`
+	Leads         int       `json:"leads"`
+}
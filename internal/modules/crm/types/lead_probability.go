@@ -0,0 +1,76 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApplyStageProbability records the default probability of the stage the
+// lead moves to, which becomes its probability unless a user overrode it.
+// A nil probability, from a stage without a default, changes nothing.
+func (l *Lead) ApplyStageProbability(probability *int) {
+	if probability == nil {
+		return
+	}
+	system := *probability
+	l.SystemProbability = &system
+	if !l.ProbabilityOverridden {
+		l.Probability = system
+	}
+}
+
+// OverrideProbability sets the lead's probability by hand, so that stage
+// moves no longer change it
+func (l *Lead) OverrideProbability(probability int) {
+	l.ManualProbability = &probability
+	l.ProbabilityOverridden = true
+	l.Probability = probability
+}
+
+// ResetProbability drops the manual probability, reverting to the stage
+// probability when the lead has one
+func (l *Lead) ResetProbability() {
+	l.ManualProbability = nil
+	l.ProbabilityOverridden = false
+	if l.SystemProbability != nil {
+		l.Probability = *l.SystemProbability
+	}
+}
+
+// LeadProbabilityAccuracyFilter represents filtering criteria for the
+// probability accuracy report
+type LeadProbabilityAccuracyFilter struct {
+	OrganizationID uuid.UUID
+	TeamID         *uuid.UUID
+	// DateFrom and DateTo bound when leads were closed
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+// ProbabilityForecast scores one kind of probability of closed leads
+// against their outcome
+type ProbabilityForecast struct {
+	Leads              int     `json:"leads"`
+	AverageProbability float64 `json:"average_probability"`
+	// WinRate is the percentage of the leads that were won, which a
+	// well calibrated forecast's average probability is close to
+	WinRate float64 `json:"win_rate"`
+	// BrierScore is the mean squared error of the probabilities, from 0
+	// for a perfect forecast to 1; lower is better
+	BrierScore float64 `json:"brier_score"`
+}
+
+// LeadProbabilityAccuracy compares the stage probabilities and the manual
+// ones of closed leads with how the leads closed
+type LeadProbabilityAccuracy struct {
+	ClosedLeads int `json:"closed_leads"`
+	WonLeads    int `json:"won_leads"`
+	// System scores the stage probability of every closed lead that had one
+	System ProbabilityForecast `json:"system"`
+	// Manual scores the probability of the overridden leads and
+	// OverriddenSystem the stage probability of the same leads, so the
+	// two show whether the overrides improved on the stage defaults
+	Manual           ProbabilityForecast `json:"manual"`
+	OverriddenSystem ProbabilityForecast `json:"overridden_system"`
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLeadReassignment wraps validation failures of lead reassignments
+var ErrInvalidLeadReassignment = errors.New("invalid lead reassignment")
+
+// LeadReassignRequest transfers the open leads of FromUserID to ToUserID or,
+// when TeamID is given instead, round-robins them across the team's other
+// available members. Reason is kept in the assignment history.
+type LeadReassignRequest struct {
+	FromUserID uuid.UUID  `json:"from_user_id"`
+	ToUserID   *uuid.UUID `json:"to_user_id,omitempty"`
+	TeamID     *uuid.UUID `json:"team_id,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// LeadReassignTarget is an open lead of the user whose leads are reassigned
+type LeadReassignTarget struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Name string    `json:"name" db:"name"`
+}
+
+// LeadReassignment hands one lead to a new owner
+type LeadReassignment struct {
+	LeadID   uuid.UUID
+	LeadName string
+	ToUserID uuid.UUID
+}
+
+// LeadReassignBatch is a planned reassignment applied in one transaction
+type LeadReassignBatch struct {
+	OrganizationID uuid.UUID
+	FromUserID     uuid.UUID
+	Assignments    []LeadReassignment
+	Reason         string
+	AssignedBy     *uuid.UUID
+	AssignedAt     time.Time
+}
+
+// LeadReassignStatus is what a reassignment did to one lead
+type LeadReassignStatus string
+
+const (
+	LeadReassignStatusReassigned LeadReassignStatus = "reassigned"
+	// LeadReassignStatusOverCapacity leads stay with their owner because
+	// every new owner reached their max capacity
+	LeadReassignStatusOverCapacity LeadReassignStatus = "over_capacity"
+	// LeadReassignStatusFailed leads changed owner or closed meanwhile
+	LeadReassignStatusFailed LeadReassignStatus = "failed"
+)
+
+// LeadReassignItemResult is the outcome of a reassignment for one lead
+type LeadReassignItemResult struct {
+	LeadID     uuid.UUID          `json:"lead_id"`
+	Status     LeadReassignStatus `json:"status"`
+	AssignedTo *uuid.UUID         `json:"assigned_to,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// LeadReassignResult is the outcome of a reassignment, oldest lead first.
+// HasMore tells that the user had more than MaxLeadBulkSize open leads and
+// the request should be repeated for the rest.
+type LeadReassignResult struct {
+	Matched      int                      `json:"matched"`
+	Reassigned   int                      `json:"reassigned"`
+	OverCapacity int                      `json:"over_capacity"`
+	Failed       int                      `json:"failed"`
+	HasMore      bool                     `json:"has_more"`
+	Results      []LeadReassignItemResult `json:"results"`
+}
+
+// LeadReassignNotice tells a new owner which leads they were handed. It is
+// published as crm.lead.reassigned, once per new owner.
+type LeadReassignNotice struct {
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	FromUserID     uuid.UUID   `json:"from_user_id"`
+	LeadIDs        []uuid.UUID `json:"lead_ids"`
+	Reason         string      `json:"reason,omitempty"`
+	AssignedBy     *uuid.UUID  `json:"assigned_by,omitempty"`
+	AssignedAt     time.Time   `json:"assigned_at"`
+}
+
+// LeadAssignedEvent tells that assignment rules changed the owner of a
+// lead. It is published as crm.lead.assigned; AssignedTo is nil when the
+// lead went back to the pool.
+type LeadAssignedEvent struct {
+	OrganizationID     uuid.UUID  `json:"organization_id"`
+	LeadID             uuid.UUID  `json:"lead_id"`
+	AssignedTo         *uuid.UUID `json:"assigned_to"`
+	PreviousAssignedTo *uuid.UUID `json:"previous_assigned_to"`
+	Reason             string     `json:"reason,omitempty"`
+	AssignedAt         time.Time  `json:"assigned_at"`
+}
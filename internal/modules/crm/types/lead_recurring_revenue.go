@@ -0,0 +1,106 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// recurringPlanPeriods is how many times a year each recurring plan bills
+var recurringPlanPeriods = map[string]float64{
+	"daily":       365,
+	"weekly":      52,
+	"monthly":     12,
+	"month":       12,
+	"quarterly":   4,
+	"quarter":     4,
+	"semiannual":  2,
+	"semi-annual": 2,
+	"half-yearly": 2,
+	"yearly":      1,
+	"year":        1,
+	"annual":      1,
+	"annually":    1,
+}
+
+// MonthlyRecurringRevenue converts the lead's recurring revenue, billed
+// every period of its recurring plan, to a monthly amount. It reports false
+// when the lead has no recurring revenue or its plan is missing or unknown.
+func (l Lead) MonthlyRecurringRevenue() (float64, bool) {
+	if l.RecurringRevenue == nil || l.RecurringPlan == nil {
+		return 0, false
+	}
+	periods, ok := recurringPlanPeriods[strings.ToLower(strings.TrimSpace(*l.RecurringPlan))]
+	if !ok {
+		return 0, false
+	}
+	return *l.RecurringRevenue * periods / 12, true
+}
+
+// RecurringRevenue is the recurring revenue of a group of leads
+type RecurringRevenue struct {
+	Leads int     `json:"leads"`
+	MRR   float64 `json:"mrr"`
+	ARR   float64 `json:"arr"`
+	// WeightedMRR and WeightedARR count each lead at its probability
+	WeightedMRR float64 `json:"weighted_mrr"`
+	WeightedARR float64 `json:"weighted_arr"`
+}
+
+// Add counts a lead with the given monthly recurring revenue
+func (r *RecurringRevenue) Add(mrr float64, probability int) {
+	r.Leads++
+	r.MRR += mrr
+	r.ARR += mrr * 12
+	r.WeightedMRR += mrr * float64(probability) / 100
+	r.WeightedARR += mrr * 12 * float64(probability) / 100
+}
+
+// StageRecurringRevenue is the recurring revenue of the leads in a stage,
+// or of those without a stage when StageID is nil
+type StageRecurringRevenue struct {
+	StageID *uuid.UUID `json:"stage_id"`
+	RecurringRevenue
+}
+
+// TeamRecurringRevenue is the recurring revenue of a team's pipeline, or
+// of the leads without a team when TeamID is nil
+type TeamRecurringRevenue struct {
+	TeamID *uuid.UUID `json:"team_id"`
+	RecurringRevenue
+}
+
+// MonthRecurringRevenue is the recurring revenue of the leads closing in a
+// month
+type MonthRecurringRevenue struct {
+	// Month is YYYY-MM, or empty for leads with neither a close date nor
+	// a deadline
+	Month string `json:"month"`
+	RecurringRevenue
+}
+
+// FiscalPeriodRecurringRevenue is the recurring revenue of the leads
+// closing in a period of the organization's fiscal calendar
+type FiscalPeriodRecurringRevenue struct {
+	// Period is the period label, such as FY2025-P07, or empty for leads
+	// with neither a close date nor a deadline
+	Period string `json:"period"`
+	RecurringRevenue
+}
+
+// RecurringRevenueReport breaks the recurring revenue of the active leads
+// that were not lost down by stage, team and close month, and fiscal close
+// period when the organization's fiscal calendar is available. Each
+// breakdown is sorted and adds up to the total.
+type RecurringRevenueReport struct {
+	// FiscalPeriod is the fiscal period the report is limited to, if any
+	FiscalPeriod  string                         `json:"fiscal_period,omitempty"`
+	Total         RecurringRevenue               `json:"total"`
+	ByStage       []StageRecurringRevenue        `json:"by_stage"`
+	ByTeam        []TeamRecurringRevenue         `json:"by_team"`
+	ByCloseMonth  []MonthRecurringRevenue        `json:"by_close_month"`
+	ByClosePeriod []FiscalPeriodRecurringRevenue `json:"by_close_period,omitempty"`
+	// UnknownPlans counts, by plan, the leads with recurring revenue left
+	// out because their plan is missing or unknown
+	UnknownPlans map[string]int `json:"unknown_plans,omitempty"`
+}
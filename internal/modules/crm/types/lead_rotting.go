@@ -0,0 +1,89 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LeadRotationResult is what became of a rotting lead
+type LeadRotationResult string
+
+const (
+	// LeadRotationReassigned leads were handed to another user
+	LeadRotationReassigned LeadRotationResult = "reassigned"
+	// LeadRotationPooled leads were returned to the pool of unassigned leads
+	LeadRotationPooled LeadRotationResult = "returned_to_pool"
+	// LeadRotationKept leads were handed back to their assignee by the rules
+	LeadRotationKept LeadRotationResult = "kept"
+	// LeadRotationNoRule leads matched no rotting assignment rule
+	LeadRotationNoRule LeadRotationResult = "no_rule"
+	// LeadRotationFailed leads could not be rotated
+	LeadRotationFailed LeadRotationResult = "failed"
+)
+
+// RottingLead is an open, assigned lead left untouched in its stage for
+// longer than the stage's rotting days. A lead is touched by an activity
+// logged on it, a stage change, a (re)assignment or an edit by a user.
+type RottingLead struct {
+	LeadID         uuid.UUID
+	OrganizationID uuid.UUID
+	LeadName       string
+	StageID        uuid.UUID
+	StageName      string
+	TeamID         *uuid.UUID
+	// TeamLeaderID is the manager told about the lead's rotation
+	TeamLeaderID  *uuid.UUID
+	AssignedTo    uuid.UUID
+	RottingDays   int
+	LastTouchedAt time.Time
+}
+
+// LeadRotation records the rotation of a rotting lead
+type LeadRotation struct {
+	ID                 uuid.UUID          `json:"id" db:"id"`
+	OrganizationID     uuid.UUID          `json:"organization_id" db:"organization_id"`
+	LeadID             uuid.UUID          `json:"lead_id" db:"lead_id"`
+	LeadName           string             `json:"lead_name,omitempty" db:"-"`
+	StageID            uuid.UUID          `json:"stage_id" db:"stage_id"`
+	TeamID             *uuid.UUID         `json:"team_id,omitempty" db:"team_id"`
+	PreviousAssignedTo uuid.UUID          `json:"previous_assigned_to" db:"previous_assigned_to"`
+	AssignedTo         *uuid.UUID         `json:"assigned_to,omitempty" db:"assigned_to"`
+	Result             LeadRotationResult `json:"result" db:"result"`
+	RottingDays        int                `json:"rotting_days" db:"rotting_days"`
+	LastTouchedAt      time.Time          `json:"last_touched_at" db:"last_touched_at"`
+	RotatedAt          time.Time          `json:"rotated_at" db:"rotated_at"`
+}
+
+// LeadRotationStatsFilter represents filtering criteria for rotation metrics
+type LeadRotationStatsFilter struct {
+	OrganizationID uuid.UUID
+	// DateFrom and DateTo bound when the leads were rotated
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+// LeadRotationStats counts the leads that rotted in a stage and what became
+// of them
+type LeadRotationStats struct {
+	StageID    uuid.UUID `json:"stage_id"`
+	StageName  string    `json:"stage_name"`
+	Rotted     int       `json:"rotted"`
+	Reassigned int       `json:"reassigned"`
+	Pooled     int       `json:"returned_to_pool"`
+	Kept       int       `json:"kept"`
+	NoRule     int       `json:"no_rule"`
+	Failed     int       `json:"failed"`
+	// AverageIdleDays is how long the leads had been untouched on average
+	AverageIdleDays float64 `json:"average_idle_days"`
+}
+
+// LeadRottingRun summarizes one pass of the rotting worker
+type LeadRottingRun struct {
+	Rotted     int `json:"rotted"`
+	Reassigned int `json:"reassigned"`
+	Pooled     int `json:"returned_to_pool"`
+	Kept       int `json:"kept"`
+	NoRule     int `json:"no_rule"`
+	Failed     int `json:"failed"`
+}
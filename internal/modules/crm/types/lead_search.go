@@ -0,0 +1,54 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidLeadSearch wraps validation failures of lead search requests
+var ErrInvalidLeadSearch = errors.New("invalid lead search")
+
+// LeadSearchRequest is a full-text search over an organization's leads.
+// StageID and Priority narrow the hits but not the facets, so the other
+// stages and priorities stay visible while drilling down.
+type LeadSearchRequest struct {
+	OrganizationID uuid.UUID
+	Query          string
+	StageID        *uuid.UUID
+	Priority       *LeadPriority
+	Limit          int
+	Offset         int
+}
+
+// LeadSearchHit is a lead matching a search, with its relevance and the
+// matched terms highlighted. Highlights are HTML escaped with the matches
+// wrapped in <mark> tags, keyed by field; fields without a match are omitted.
+type LeadSearchHit struct {
+	Lead       Lead              `json:"lead"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// LeadSearchFacet counts the leads matching a search for one value of a
+// field. Label is the stage name for stage facets.
+type LeadSearchFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// LeadSearchFacets counts the leads matching a search by stage and by
+// priority. Leads without a stage are counted under an empty value.
+type LeadSearchFacets struct {
+	Stage    []LeadSearchFacet `json:"stage"`
+	Priority []LeadSearchFacet `json:"priority"`
+}
+
+// LeadSearchResult is a page of hits in relevance order. Total counts the
+// hits across all pages.
+type LeadSearchResult struct {
+	Total  int              `json:"total"`
+	Hits   []LeadSearchHit  `json:"hits"`
+	Facets LeadSearchFacets `json:"facets"`
+}
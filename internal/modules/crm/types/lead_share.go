@@ -0,0 +1,82 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidLeadShareLink is returned for share link requests that fail validation
+	ErrInvalidLeadShareLink = errors.New("invalid lead share link")
+	// ErrLeadShareLinkNotFound is returned for unknown, expired or revoked
+	// share tokens
+	ErrLeadShareLinkNotFound = errors.New("lead share link not found")
+)
+
+// LeadShareFields are the lead fields, by JSON name, that share links may
+// show. Custom fields are shown with their custom.<name>.
+var LeadShareFields = map[string]bool{
+	"id": true, "name": true, "contact_name": true, "email": true, "phone": true, "mobile": true,
+	"company_id": true, "contact_id": true, "user_id": true, "team_id": true, "assigned_to": true,
+	"lead_type": true, "stage_id": true, "priority": true, "status": true, "won_status": true,
+	"lost_reason_id": true, "source_id": true, "medium_id": true, "campaign_id": true,
+	"expected_revenue": true, "probability": true, "recurring_revenue": true, "recurring_plan": true,
+	"date_open": true, "date_closed": true, "date_deadline": true, "date_last_stage_update": true,
+	"street": true, "street2": true, "city": true, "state_id": true, "zip": true, "country_id": true,
+	"website": true, "description": true, "tag_ids": true, "created_at": true, "updated_at": true,
+}
+
+// DefaultLeadShareFields are shown when a link does not list its fields:
+// the pipeline figures, without the lead's contact details
+var DefaultLeadShareFields = []string{"name", "stage_id", "priority", "status", "expected_revenue", "probability", "date_deadline"}
+
+// LeadShareLink is a read-only view of an organization's leads shared
+// through a public link until it expires or is revoked. Only Fields are
+// shown of the leads matching Query.
+type LeadShareLink struct {
+	ID             uuid.UUID     `json:"id" db:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id" db:"organization_id"`
+	Name           string        `json:"name" db:"name"`
+	TokenHash      string        `json:"-" db:"token_hash"`
+	Query          *query.Node   `json:"query,omitempty" db:"query"`
+	SortBy         LeadSortField `json:"sort_by,omitempty" db:"sort_by"`
+	SortDir        SortDirection `json:"sort_dir,omitempty" db:"sort_dir"`
+	Fields         []string      `json:"fields" db:"fields"`
+	ExpiresAt      time.Time     `json:"expires_at" db:"expires_at"`
+	RevokedAt      *time.Time    `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy      *uuid.UUID    `json:"revoked_by,omitempty" db:"revoked_by"`
+	ViewCount      int           `json:"view_count" db:"view_count"`
+	LastViewedAt   *time.Time    `json:"last_viewed_at,omitempty" db:"last_viewed_at"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	CreatedBy      *uuid.UUID    `json:"created_by,omitempty" db:"created_by"`
+	// SharePath is the public view, relative to the API host. It is only
+	// returned when the link is created, as the token is not stored.
+	SharePath string `json:"share_path,omitempty" db:"-"`
+}
+
+// LeadShareLinkRequest creates a share link. ExpiresAt defaults to a week
+// from now; Fields to DefaultLeadShareFields.
+type LeadShareLinkRequest struct {
+	Name      string      `json:"name"`
+	Query     *query.Node `json:"query,omitempty"`
+	SortBy    string      `json:"sort_by,omitempty"`
+	SortDir   string      `json:"sort_dir,omitempty"`
+	Fields    []string    `json:"fields,omitempty"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+}
+
+// PublicLeadView is a page of a share link's leads, each with only the
+// link's fields
+type PublicLeadView struct {
+	Name      string                   `json:"name"`
+	Fields    []string                 `json:"fields"`
+	ExpiresAt time.Time                `json:"expires_at"`
+	Total     int                      `json:"total"`
+	Limit     int                      `json:"limit"`
+	Offset    int                      `json:"offset"`
+	Data      []map[string]interface{} `json:"data"`
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidLeadSLAPolicy wraps validation failures of SLA policies
+	ErrInvalidLeadSLAPolicy = errors.New("invalid lead SLA policy")
+	// ErrLeadSLAPolicyExists is returned when the organization already has a
+	// policy for the same stage and priority
+	ErrLeadSLAPolicyExists = errors.New("a lead SLA policy already exists for this stage and priority")
+)
+
+// LeadSLAPolicy is a response-time target for leads. A nil StageID or
+// Priority matches every stage or priority.
+type LeadSLAPolicy struct {
+	ID              uuid.UUID     `json:"id" db:"id"`
+	OrganizationID  uuid.UUID     `json:"organization_id" db:"organization_id"`
+	Name            string        `json:"name" db:"name"`
+	StageID         *uuid.UUID    `json:"stage_id,omitempty" db:"stage_id"`
+	Priority        *LeadPriority `json:"priority,omitempty" db:"priority"`
+	ResponseMinutes int           `json:"response_minutes" db:"response_minutes"`
+	// Escalate reassigns breached leads through the assignment rules
+	Escalate  bool       `json:"escalate" db:"escalate"`
+	Active    bool       `json:"active" db:"active"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// LeadSLAPolicyRequest represents a request to create or update an SLA policy
+type LeadSLAPolicyRequest struct {
+	Name            string        `json:"name"`
+	StageID         *uuid.UUID    `json:"stage_id,omitempty"`
+	Priority        *LeadPriority `json:"priority,omitempty"`
+	ResponseMinutes int           `json:"response_minutes"`
+	Escalate        *bool         `json:"escalate,omitempty"`
+	Active          *bool         `json:"active,omitempty"`
+}
+
+// LeadSLAClock times the response to a lead after it entered a stage
+type LeadSLAClock struct {
+	ID             uuid.UUID    `json:"id" db:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id" db:"organization_id"`
+	LeadID         uuid.UUID    `json:"lead_id" db:"lead_id"`
+	LeadName       string       `json:"lead_name,omitempty" db:"lead_name"`
+	PolicyID       *uuid.UUID   `json:"policy_id,omitempty" db:"policy_id"`
+	TeamID         *uuid.UUID   `json:"team_id,omitempty" db:"team_id"`
+	StageID        *uuid.UUID   `json:"stage_id,omitempty" db:"stage_id"`
+	Priority       LeadPriority `json:"priority" db:"priority"`
+	AssignedTo     *uuid.UUID   `json:"assigned_to,omitempty" db:"assigned_to"`
+	Escalate       bool         `json:"escalate" db:"escalate"`
+	StartedAt      time.Time    `json:"started_at" db:"started_at"`
+	DueAt          time.Time    `json:"due_at" db:"due_at"`
+	RespondedAt    *time.Time   `json:"responded_at,omitempty" db:"responded_at"`
+	BreachedAt     *time.Time   `json:"breached_at,omitempty" db:"breached_at"`
+	EscalatedAt    *time.Time   `json:"escalated_at,omitempty" db:"escalated_at"`
+	EscalatedTo    *uuid.UUID   `json:"escalated_to,omitempty" db:"escalated_to"`
+	// EscalationResult is the outcome of reassigning the breached lead
+	EscalationResult *string `json:"escalation_result,omitempty" db:"escalation_result"`
+}
+
+// LeadSLAEntry is an open lead's current stage entry that an active policy
+// applies to but that has no clock yet
+type LeadSLAEntry struct {
+	LeadID          uuid.UUID
+	OrganizationID  uuid.UUID
+	PolicyID        uuid.UUID
+	TeamID          *uuid.UUID
+	StageID         *uuid.UUID
+	Priority        LeadPriority
+	AssignedTo      *uuid.UUID
+	ResponseMinutes int
+	Escalate        bool
+	EnteredAt       time.Time
+}
+
+// LeadSLABreachFilter represents filtering criteria for SLA breaches
+type LeadSLABreachFilter struct {
+	OrganizationID uuid.UUID
+	TeamID         *uuid.UUID
+	AssignedTo     *uuid.UUID
+	// Open limits the breaches to leads still waiting for a response
+	Open bool
+	// DateFrom and DateTo bound when the breach happened
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Limit    int
+	Offset   int
+}
+
+// LeadSLAComplianceFilter represents filtering criteria for SLA compliance
+type LeadSLAComplianceFilter struct {
+	OrganizationID uuid.UUID
+	// DateFrom and DateTo bound when the clocks started
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+// LeadSLACompliance summarizes how a sales team met its response targets.
+// Clocks still running within their target are pending and left out of the
+// compliance rate.
+type LeadSLACompliance struct {
+	TeamID                 *uuid.UUID `json:"team_id,omitempty"`
+	TeamName               string     `json:"team_name"`
+	Total                  int        `json:"total"`
+	Met                    int        `json:"met"`
+	Breached               int        `json:"breached"`
+	Pending                int        `json:"pending"`
+	Escalated              int        `json:"escalated"`
+	ComplianceRate         float64    `json:"compliance_rate"`
+	AverageResponseMinutes float64    `json:"average_response_minutes"`
+}
+
+// LeadSLARun summarizes one pass of the SLA worker
+type LeadSLARun struct {
+	Started   int `json:"started"`
+	Responded int `json:"responded"`
+	Breached  int `json:"breached"`
+	Escalated int `json:"escalated"`
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -18,6 +20,7 @@ type LeadSource struct {
 type LeadSourceFilter struct {
 	OrganizationID uuid.UUID
 	Name           *string
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
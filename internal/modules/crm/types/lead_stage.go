@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -12,11 +14,16 @@ type LeadStage struct {
 	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
 	Name         string     `json:"name" db:"name"`
 	Sequence     int        `json:"sequence" db:"sequence"`
-	Probability  int        `json:"probability" db:"probability"`
+	// Probability is the default probability of leads moving into the
+	// stage; leads keep theirs when it is nil
+	Probability  *int       `json:"probability" db:"probability"`
 	Fold         bool       `json:"fold" db:"fold"`
 	IsWon        bool       `json:"is_won" db:"is_won"`
 	Requirements *string    `json:"requirements,omitempty" db:"requirements"`
 	TeamID       *uuid.UUID `json:"team_id,omitempty" db:"team_id"`
+	// RottingDays is how many days a lead may sit in the stage untouched
+	// before it is rotated away from its assignee; nil leads never rot
+	RottingDays  *int       `json:"rotting_days,omitempty" db:"rotting_days"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -27,6 +34,7 @@ type LeadStageFilter struct {
 	Name           *string
 	IsWon          *bool
 	TeamID         *uuid.UUID
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -35,11 +43,12 @@ type LeadStageFilter struct {
 type LeadStageCreateRequest struct {
 	Name        string     `json:"name"`
 	Sequence    int        `json:"sequence"`
-	Probability int        `json:"probability"`
+	Probability *int       `json:"probability,omitempty"`
 	Fold        bool       `json:"fold"`
 	IsWon       bool       `json:"is_won"`
 	Requirements *string    `json:"requirements,omitempty"`
 	TeamID      *uuid.UUID `json:"team_id,omitempty"`
+	RottingDays *int       `json:"rotting_days,omitempty"`
 }
 
 // LeadStageUpdateRequest represents a request to update a lead stage
@@ -51,4 +60,5 @@ type LeadStageUpdateRequest struct {
 	IsWon       *bool      `json:"is_won,omitempty"`
 	Requirements *string    `json:"requirements,omitempty"`
 	TeamID      *uuid.UUID `json:"team_id,omitempty"`
+	RottingDays *int       `json:"rotting_days,omitempty"`
 }
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrStageTransitionNotAllowed is returned when a lead's pipeline does
+	// not allow moving it to the requested stage
+	ErrStageTransitionNotAllowed = errors.New("stage transition not allowed")
+	// ErrLeadStageChanged is returned when a lead moved stage while another
+	// move of it was in progress
+	ErrLeadStageChanged = errors.New("lead stage changed concurrently")
+)
+
+// LeadStageChange records a lead moving from one stage to another
+type LeadStageChange struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	LeadID         uuid.UUID  `json:"lead_id" db:"lead_id"`
+	TeamID         *uuid.UUID `json:"team_id,omitempty" db:"team_id"`
+	FromStageID    *uuid.UUID `json:"from_stage_id,omitempty" db:"from_stage_id"`
+	ToStageID      *uuid.UUID `json:"to_stage_id,omitempty" db:"to_stage_id"`
+	ChangedBy      *uuid.UUID `json:"changed_by,omitempty" db:"changed_by"`
+	ChangedAt      time.Time  `json:"changed_at" db:"changed_at"`
+	// DurationSeconds is how long the lead spent in the from stage
+	DurationSeconds int64   `json:"duration_seconds" db:"duration_seconds"`
+	Note            *string `json:"note,omitempty" db:"note"`
+}
+
+// LeadMoveStageRequest represents a request to move a lead to another stage
+type LeadMoveStageRequest struct {
+	StageID uuid.UUID `json:"stage_id"`
+	Note    *string   `json:"note,omitempty"`
+}
+
+// LeadStageTransition is a move a pipeline allows
+type LeadStageTransition struct {
+	FromStageID uuid.UUID `json:"from_stage_id"`
+	ToStageID   uuid.UUID `json:"to_stage_id"`
+}
+
+// LeadStageTransitions are the moves allowed in a pipeline. A nil TeamID is
+// the organization default, used by teams without transitions of their own.
+type LeadStageTransitions struct {
+	TeamID      *uuid.UUID            `json:"team_id,omitempty"`
+	Transitions []LeadStageTransition `json:"transitions"`
+}
+
+// Allows reports whether a lead may move from one stage to another. A
+// pipeline without transitions allows every move, and a lead without a
+// stage may enter the pipeline at any stage.
+func (t LeadStageTransitions) Allows(from *uuid.UUID, to uuid.UUID) bool {
+	if len(t.Transitions) == 0 || from == nil {
+		return true
+	}
+	for _, transition := range t.Transitions {
+		if transition.FromStageID == *from && transition.ToStageID == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StageVelocityFilter represents filtering criteria for stage velocity
+type StageVelocityFilter struct {
+	OrganizationID uuid.UUID
+	TeamID         *uuid.UUID
+	// DateFrom and DateTo bound when leads left the stage
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+// StageVelocity is how quickly leads move through a stage
+type StageVelocity struct {
+	StageID   uuid.UUID `json:"stage_id"`
+	StageName string    `json:"stage_name"`
+	Sequence  int       `json:"sequence"`
+	// Exits counts the moves out of the stage in the period
+	Exits          int     `json:"exits"`
+	AverageDays    float64 `json:"average_days"`
+	MedianDays     float64 `json:"median_days"`
+	CurrentLeads   int     `json:"current_leads"`
+	AverageAgeDays float64 `json:"average_age_days"`
+}
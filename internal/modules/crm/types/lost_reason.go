@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -20,6 +22,7 @@ type LostReasonFilter struct {
 	OrganizationID uuid.UUID
 	Name           *string
 	Active         *bool
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -0,0 +1,85 @@
+package types
+
+import "github.com/KevTiv/alieze-erp/pkg/query"
+
+// LeadQuerySchema is what lead search queries may filter on, by the
+// lead's JSON field names. Custom fields are named custom.<name>.
+var LeadQuerySchema = query.Schema{
+	Fields: map[string]query.Field{
+		"name":                   {Column: "name", Type: query.TypeText},
+		"contact_name":           {Column: "contact_name", Type: query.TypeText},
+		"email":                  {Column: "email", Type: query.TypeText},
+		"phone":                  {Column: "phone", Type: query.TypeText},
+		"mobile":                 {Column: "mobile", Type: query.TypeText},
+		"website":                {Column: "website", Type: query.TypeText},
+		"city":                   {Column: "city", Type: query.TypeText},
+		"zip":                    {Column: "zip", Type: query.TypeText},
+		"description":            {Column: "description", Type: query.TypeText},
+		"lead_type":              {Column: "lead_type", Type: query.TypeText, Options: []string{string(LeadTypeLead), string(LeadTypeOpportunity)}},
+		"priority":               {Column: "priority", Type: query.TypeText, Options: []string{string(LeadPriorityLow), string(LeadPriorityMedium), string(LeadPriorityHigh), string(LeadPriorityUrgent)}},
+		"status":                 {Column: "status", Type: query.TypeText, Options: []string{string(LeadStatusNew), string(LeadStatusInProgress), string(LeadStatusWon), string(LeadStatusLost), string(LeadStatusArchived)}},
+		"won_status":             {Column: "won_status", Type: query.TypeText, Options: []string{string(LeadWonStatusWon), string(LeadWonStatusLost), string(LeadWonStatusOngoing)}},
+		"expected_revenue":       {Column: "expected_revenue", Type: query.TypeNumber},
+		"probability":            {Column: "probability", Type: query.TypeNumber},
+		"recurring_revenue":      {Column: "recurring_revenue", Type: query.TypeNumber},
+		"active":                 {Column: "active", Type: query.TypeBoolean},
+		"date_open":              {Column: "date_open", Type: query.TypeTime},
+		"date_closed":            {Column: "date_closed", Type: query.TypeTime},
+		"date_deadline":          {Column: "date_deadline", Type: query.TypeTime},
+		"date_last_stage_update": {Column: "date_last_stage_update", Type: query.TypeTime},
+		"created_at":             {Column: "created_at", Type: query.TypeTime},
+		"updated_at":             {Column: "updated_at", Type: query.TypeTime},
+		"company_id":             {Column: "company_id", Type: query.TypeUUID},
+		"contact_id":             {Column: "contact_id", Type: query.TypeUUID},
+		"user_id":                {Column: "user_id", Type: query.TypeUUID},
+		"team_id":                {Column: "team_id", Type: query.TypeUUID},
+		"stage_id":               {Column: "stage_id", Type: query.TypeUUID},
+		"source_id":              {Column: "source_id", Type: query.TypeUUID},
+		"medium_id":              {Column: "medium_id", Type: query.TypeUUID},
+		"campaign_id":            {Column: "campaign_id", Type: query.TypeUUID},
+		"assigned_to":            {Column: "assigned_to", Type: query.TypeUUID},
+		"lost_reason_id":         {Column: "lost_reason_id", Type: query.TypeUUID},
+		"country_id":             {Column: "country_id", Type: query.TypeUUID},
+		"state_id":               {Column: "state_id", Type: query.TypeUUID},
+		"created_by":             {Column: "created_by", Type: query.TypeUUID},
+		"tags":                   {Column: "tag_ids", Type: query.TypeUUIDList},
+	},
+	CustomFields: "custom_fields",
+}
+
+// ContactQuerySchema is what contact search queries may filter on, by the
+// contact's field names. Custom fields are named custom.<name>.
+var ContactQuerySchema = query.Schema{
+	Fields: map[string]query.Field{
+		"name":               {Column: "name", Type: query.TypeText},
+		"display_name":       {Column: "display_name", Type: query.TypeText},
+		"email":              {Column: "email", Type: query.TypeText},
+		"phone":              {Column: "phone", Type: query.TypeText},
+		"mobile":             {Column: "mobile", Type: query.TypeText},
+		"website":            {Column: "website", Type: query.TypeText},
+		"job_position":       {Column: "job_position", Type: query.TypeText},
+		"city":               {Column: "city", Type: query.TypeText},
+		"zip":                {Column: "zip", Type: query.TypeText},
+		"language":           {Column: "language", Type: query.TypeText},
+		"contact_type":       {Column: "contact_type", Type: query.TypeText, Options: []string{"person", "company"}},
+		"is_company":         {Column: "is_company", Type: query.TypeBoolean},
+		"is_customer":        {Column: "is_customer", Type: query.TypeBoolean},
+		"is_vendor":          {Column: "is_vendor", Type: query.TypeBoolean},
+		"is_employee":        {Column: "is_employee", Type: query.TypeBoolean},
+		"engagement_score":   {Column: "engagement_score", Type: query.TypeNumber},
+		"lead_score":         {Column: "lead_score", Type: query.TypeNumber},
+		"data_quality_score": {Column: "data_quality_score", Type: query.TypeNumber},
+		"last_contacted_at":  {Column: "last_contacted_at", Type: query.TypeTime},
+		"created_at":         {Column: "created_at", Type: query.TypeTime},
+		"updated_at":         {Column: "updated_at", Type: query.TypeTime},
+		"company_id":         {Column: "company_id", Type: query.TypeUUID},
+		"parent_id":          {Column: "parent_id", Type: query.TypeUUID},
+		"user_id":            {Column: "user_id", Type: query.TypeUUID},
+		"team_id":            {Column: "team_id", Type: query.TypeUUID},
+		"industry_id":        {Column: "industry_id", Type: query.TypeUUID},
+		"country_id":         {Column: "country_id", Type: query.TypeUUID},
+		"state_id":           {Column: "state_id", Type: query.TypeUUID},
+		"tags":               {Column: "tags", Type: query.TypeUUIDList},
+	},
+	CustomFields: "custom_fields",
+}
@@ -41,15 +41,26 @@ type ContactTagRepository interface {
 type LeadRepository interface {
 	CRUDRepository[Lead, LeadFilter]
 
+	// FindPage returns a page of leads and, when withTotal is set, the
+	// total of all matching leads counted in the same query
+	FindPage(ctx context.Context, filter LeadFilter, withTotal bool) (*LeadPage, error)
+
 	// Date range queries
-	FindByDateRange(ctx context.Context, startDate, endDate time.Time) ([]Lead, error)
-	FindByDeadlineRange(ctx context.Context, startDate, endDate time.Time) ([]Lead, error)
+	FindByDateRange(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]Lead, error)
+	FindByDeadlineRange(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]Lead, error)
 
 	// Utility methods
-	CountByStage(ctx context.Context) (map[uuid.UUID]int, error)
-	FindOverdue(ctx context.Context) ([]Lead, error)
-	FindHighValue(ctx context.Context, minValue float64) ([]Lead, error)
-	FindBySearchTerm(ctx context.Context, searchTerm string) ([]Lead, error)
+	CountByStage(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]int, error)
+	FindOverdue(ctx context.Context, orgID uuid.UUID) ([]Lead, error)
+	FindHighValue(ctx context.Context, orgID uuid.UUID, minValue float64) ([]Lead, error)
+	FindBySearchTerm(ctx context.Context, orgID uuid.UUID, searchTerm string) ([]Lead, error)
+
+	// Deduplication
+	FindOpenByEmailSince(ctx context.Context, orgID uuid.UUID, email string, since time.Time) (*Lead, error)
+}
+
+type LeadCaptureFormRepository interface {
+	CRUDRepository[LeadCaptureForm, LeadCaptureFormFilter]
 }
 
 // Other domain repositories
@@ -57,6 +68,333 @@ type LeadStageRepository interface {
 	CRUDRepository[LeadStage, LeadStageFilter]
 }
 
+// LeadStageHistoryRepository records lead stage changes and the transitions
+// each pipeline allows
+type LeadStageHistoryRepository interface {
+	// MoveStage moves the lead and records the change in one transaction,
+	// and applies the stage probability, if any, unless it was overridden
+	MoveStage(ctx context.Context, change LeadStageChange, probability *int) (*LeadStageChange, error)
+	FindByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]LeadStageChange, error)
+	Velocity(ctx context.Context, filter StageVelocityFilter) ([]StageVelocity, error)
+
+	// Transitions falls back to the organization default when the team has none
+	FindTransitions(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*LeadStageTransitions, error)
+	ReplaceTransitions(ctx context.Context, orgID uuid.UUID, transitions LeadStageTransitions) error
+}
+
+// LeadConversionRepository converts leads to opportunities
+type LeadConversionRepository interface {
+	// Convert links or creates the contact, turns the lead into an
+	// opportunity and raises the quotation, if any, in one transaction
+	Convert(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID, userID *uuid.UUID, req LeadConvertRequest, convertedAt time.Time) (*LeadConversion, error)
+}
+
+// LeadOutcomeRepository closes leads as won or lost
+type LeadOutcomeRepository interface {
+	// Close locks the open lead, records the outcome on it and logs the
+	// note, if any, as a done note activity, in one transaction
+	Close(ctx context.Context, outcome LeadOutcome) (*LeadOutcome, error)
+	// ProbabilityAccuracy scores the system and manual probabilities of
+	// the closed leads against their outcome
+	ProbabilityAccuracy(ctx context.Context, filter LeadProbabilityAccuracyFilter) (*LeadProbabilityAccuracy, error)
+}
+
+// LeadSearchRepository runs full-text searches over leads
+type LeadSearchRepository interface {
+	// Search returns a page of matching leads in relevance order, with the
+	// total and the facet counts of all matches
+	Search(ctx context.Context, req LeadSearchRequest) (*LeadSearchResult, error)
+}
+
+// LeadBoardRepository reads the leads of the kanban board, grouped by stage
+type LeadBoardRepository interface {
+	// Totals counts the filtered leads of each stage and sums their
+	// expected revenue
+	Totals(ctx context.Context, filter LeadFilter) ([]LeadBoardTotals, error)
+	// Cards returns up to limit filtered leads of each stage in the filter's
+	// order, skipping the number of leads offsets gives for a stage; uuid.Nil
+	// keys the leads without a stage
+	Cards(ctx context.Context, filter LeadFilter, limit int, offsets map[uuid.UUID]int) ([]*Lead, error)
+}
+
+// ContactScoreRepository reads the activity data contacts are scored from
+// and stores score weights and daily scores
+type ContactScoreRepository interface {
+	// Weights returns the organization's weights, or the defaults when it
+	// has none
+	Weights(ctx context.Context, orgID uuid.UUID) (*ContactScoreWeights, error)
+	SaveWeights(ctx context.Context, weights ContactScoreWeights) (*ContactScoreWeights, error)
+
+	// Signals returns the engagement signals of a contact at a moment
+	Signals(ctx context.Context, orgID uuid.UUID, contactID uuid.UUID, at time.Time, window time.Duration) (*ContactEngagementSignals, error)
+	// SignalsBatch returns the signals of up to limit live contacts, across
+	// organizations, with IDs after the given one in ID order
+	SignalsBatch(ctx context.Context, after uuid.UUID, limit int, at time.Time, window time.Duration) ([]ContactEngagementSignals, error)
+
+	// SaveSnapshots stores daily scores, replacing those of the same
+	// contact and day
+	SaveSnapshots(ctx context.Context, snapshots []ContactScoreSnapshot) error
+	History(ctx context.Context, filter ContactScoreHistoryFilter) ([]ContactScoreSnapshot, error)
+}
+
+// ContactGDPRRepository gathers the data held about contacts, anonymizes
+// them and keeps the audit record of both
+type ContactGDPRRepository interface {
+	// ExportSections returns every section of the contact's data in
+	// ContactGDPRSections order
+	ExportSections(ctx context.Context, orgID, contactID uuid.UUID) ([]ContactGDPRSection, error)
+	// AnonymizedAt returns when the live contact was anonymized, or nil
+	AnonymizedAt(ctx context.Context, orgID, contactID uuid.UUID) (*time.Time, error)
+
+	// CreateRequest records a request, returning
+	// ErrContactGDPRRequestPending for a second pending anonymization
+	CreateRequest(ctx context.Context, req *ContactGDPRRequest) error
+	PendingAnonymization(ctx context.Context, orgID, contactID uuid.UUID) (*ContactGDPRRequest, error)
+	ListRequests(ctx context.Context, orgID, contactID uuid.UUID) ([]ContactGDPRRequest, error)
+
+	// Anonymize pseudonymizes the contact of the pending request and
+	// completes it in one transaction
+	Anonymize(ctx context.Context, orgID, requestID, reviewedBy uuid.UUID, note string) (*ContactGDPRRequest, error)
+	Reject(ctx context.Context, orgID, requestID, reviewedBy uuid.UUID, note string) (*ContactGDPRRequest, error)
+}
+
+// LeadShareRepository stores the public share links of lead views
+type LeadShareRepository interface {
+	CreateShareLink(ctx context.Context, link LeadShareLink) (*LeadShareLink, error)
+	ListShareLinks(ctx context.Context, orgID uuid.UUID) ([]LeadShareLink, error)
+	// RevokeShareLink ends a link at the given time; revoking it again
+	// changes nothing
+	RevokeShareLink(ctx context.Context, orgID, id uuid.UUID, revokedBy *uuid.UUID, at time.Time) (*LeadShareLink, error)
+	// ViewShareLink returns the link of a token hash that is neither
+	// expired nor revoked at the given time, counting the view
+	ViewShareLink(ctx context.Context, tokenHash string, at time.Time) (*LeadShareLink, error)
+}
+
+// SyncRepository finds the changes the mobile app pulls and keeps the
+// operations it pushed
+type SyncRepository interface {
+	// Changes returns the records of an entity written or deleted after the
+	// cursor's position and no later than until, in the order they changed
+	Changes(ctx context.Context, orgID uuid.UUID, entity SyncEntity, after SyncMarker, until time.Time, limit int) ([]SyncMarker, error)
+	// FindRecord returns where a record is at, flagged as deleted when
+	// soft-deleted, or nil when there is no such record
+	FindRecord(ctx context.Context, orgID uuid.UUID, entity SyncEntity, id uuid.UUID) (*SyncMarker, error)
+	// FindAppliedOp returns nil for an operation not applied
+	FindAppliedOp(ctx context.Context, orgID, clientID uuid.UUID) (*SyncAppliedOp, error)
+	// RecordAppliedOp keeps an operation; keeping one again changes nothing
+	RecordAppliedOp(ctx context.Context, op SyncAppliedOp) error
+}
+
+// ContactSearchRepository searches contacts with an advanced filter, whose
+// query the service has compiled
+type ContactSearchRepository interface {
+	Search(ctx context.Context, filter AdvancedContactFilter) ([]*Contact, error)
+	Count(ctx context.Context, filter AdvancedContactFilter) (int, error)
+}
+
+// ContactConsentRepository stores contacts' communication preferences and
+// their history
+type ContactConsentRepository interface {
+	// ListConsents returns the channels the live contact stated a
+	// preference for, and sql.ErrNoRows when there is no such contact
+	ListConsents(ctx context.Context, orgID, contactID uuid.UUID) ([]ContactConsent, error)
+	// SaveConsent stores the consent and records event, with the status it
+	// replaces, in one transaction
+	SaveConsent(ctx context.Context, consent ContactConsent, event ContactConsentEvent) (*ContactConsent, error)
+	// ConfirmConsent opts in the pending consent with the confirmation
+	// token hash, sent after sentAfter, and records event. It returns
+	// ErrConsentTokenNotFound when there is none.
+	ConfirmConsent(ctx context.Context, tokenHash string, sentAfter time.Time, event ContactConsentEvent) (*ContactConsent, error)
+	ListConsentEvents(ctx context.Context, orgID, contactID uuid.UUID) ([]ContactConsentEvent, error)
+
+	// UnsubscribeToken returns the contact's unsubscribe token, giving it
+	// token when it has none
+	UnsubscribeToken(ctx context.Context, orgID, contactID uuid.UUID, token string) (string, error)
+	// FindUnsubscribeToken returns the organization and contact of an
+	// unsubscribe token, or ErrConsentTokenNotFound
+	FindUnsubscribeToken(ctx context.Context, token string) (orgID, contactID uuid.UUID, err error)
+}
+
+// LeadBulkRepository selects and changes leads in bulk
+type LeadBulkRepository interface {
+	// FindTargets returns the live leads with the given IDs in that order or,
+	// when ids is empty, up to limit leads matching the filter by name
+	FindTargets(ctx context.Context, filter LeadFilter, ids []uuid.UUID, limit int) ([]LeadBulkTarget, error)
+	// Update changes the leads in one transaction, locking them in ID order,
+	// and records the stage moves and changed fields. It returns a result
+	// per lead in batch order; with AllOrNothing, any failed lead rolls the
+	// batch back.
+	Update(ctx context.Context, batch LeadBulkBatch) ([]LeadBulkItemResult, error)
+	// Delete soft-deletes the live leads among ids and returns them
+	Delete(ctx context.Context, orgID uuid.UUID, ids []uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error)
+}
+
+// LeadSLARepository stores SLA policies and the response clocks of leads
+type LeadSLARepository interface {
+	CreatePolicy(ctx context.Context, policy LeadSLAPolicy) (*LeadSLAPolicy, error)
+	FindPolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*LeadSLAPolicy, error)
+	ListPolicies(ctx context.Context, orgID uuid.UUID) ([]LeadSLAPolicy, error)
+	UpdatePolicy(ctx context.Context, policy LeadSLAPolicy) (*LeadSLAPolicy, error)
+	DeletePolicy(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error
+
+	// UntimedEntries returns the stage entries of open leads, across
+	// organizations, that an active policy applies to but have no clock yet
+	UntimedEntries(ctx context.Context, limit int) ([]LeadSLAEntry, error)
+	// StartClock ignores a clock already started for the same stage entry
+	StartClock(ctx context.Context, clock LeadSLAClock) error
+	// RecordResponses stops the running clocks of leads that were responded
+	// to, flagging the late responses as breaches. Emails received from the
+	// lead and meetings it booked are not responses, nor are cadence
+	// activities until done.
+	RecordResponses(ctx context.Context) (int, error)
+	// FlagBreaches flags the running clocks past due and returns them
+	FlagBreaches(ctx context.Context, now time.Time) ([]LeadSLAClock, error)
+	// PendingEscalations returns breached clocks of escalating policies that
+	// still wait for a response and were not escalated
+	PendingEscalations(ctx context.Context, limit int) ([]LeadSLAClock, error)
+	MarkEscalated(ctx context.Context, id uuid.UUID, escalatedTo *uuid.UUID, result string, at time.Time) error
+
+	Breaches(ctx context.Context, filter LeadSLABreachFilter) ([]LeadSLAClock, error)
+	Compliance(ctx context.Context, filter LeadSLAComplianceFilter) ([]LeadSLACompliance, error)
+}
+
+// LeadRottingRepository finds the leads left untouched past their stage's
+// rotting days and records their rotations
+type LeadRottingRepository interface {
+	// RottingLeads returns, across organizations, the rotting leads not
+	// rotated since they were last touched, the longest untouched first.
+	// Leads whose rotation failed are returned again.
+	RottingLeads(ctx context.Context, now time.Time, limit int) ([]RottingLead, error)
+	RecordRotation(ctx context.Context, rotation LeadRotation) error
+	RotationStats(ctx context.Context, filter LeadRotationStatsFilter) ([]LeadRotationStats, error)
+}
+
+// LeadTrashRepository lists soft-deleted leads and brings them back. Leads
+// are hard-deleted from the trash by the retention module.
+type LeadTrashRepository interface {
+	// ListDeleted returns a page of the organization's soft-deleted leads,
+	// the most recently deleted first, with the total
+	ListDeleted(ctx context.Context, orgID uuid.UUID, limit, offset int) (*LeadPage, error)
+	// Restore undeletes the lead and records it in the audit log
+	Restore(ctx context.Context, orgID, id uuid.UUID, restoredBy *uuid.UUID, restoredAt time.Time) (*Lead, error)
+}
+
+// LeadReassignRepository transfers the open leads of a user to new owners
+type LeadReassignRepository interface {
+	// OpenLeads returns up to limit new or in-progress live leads assigned
+	// to the user, oldest first
+	OpenLeads(ctx context.Context, orgID, userID uuid.UUID, limit int) ([]LeadReassignTarget, error)
+	// Reassign hands the leads still assigned to the batch's user to their
+	// new owners in one transaction, recording the assignment history, the
+	// audit log and the owners' loads. It returns the leads reassigned.
+	Reassign(ctx context.Context, batch LeadReassignBatch) ([]uuid.UUID, error)
+}
+
+// LeadPipelineSnapshotRepository takes weekly snapshots of the open
+// pipeline and reads them back for week over week reports
+type LeadPipelineSnapshotRepository interface {
+	// Capture snapshots, across organizations, the open leads for the week
+	// starting at weekStart. Organizations already captured that week are
+	// left as they are.
+	Capture(ctx context.Context, weekStart time.Time) (*LeadPipelineSnapshotRun, error)
+	// LatestWeek returns the start of the organization's latest snapshot
+	// week on or before the given week, or ErrNoPipelineSnapshot
+	LatestWeek(ctx context.Context, orgID uuid.UUID, onOrBefore time.Time) (time.Time, error)
+	// Totals returns the snapshot of the week per team and stage, of one
+	// team when teamID is given
+	Totals(ctx context.Context, orgID uuid.UUID, weekStart time.Time, teamID *uuid.UUID) ([]LeadPipelineSnapshotTotal, error)
+	// Movement compares where the leads stood in two snapshot weeks, per
+	// team and stage
+	Movement(ctx context.Context, orgID uuid.UUID, weekStart, previousWeekStart time.Time, teamID *uuid.UUID) ([]LeadPipelineStageMovement, error)
+}
+
+// LeadCadenceRepository stores follow-up cadences and the enrollments of
+// the leads going through them
+type LeadCadenceRepository interface {
+	CreateCadence(ctx context.Context, cadence LeadCadence) (*LeadCadence, error)
+	FindCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*LeadCadence, error)
+	ListCadences(ctx context.Context, orgID uuid.UUID) ([]LeadCadence, error)
+	// UpdateCadence replaces the cadence's fields and steps
+	UpdateCadence(ctx context.Context, cadence LeadCadence) (*LeadCadence, error)
+	// DeleteCadence removes the cadence, stopping its active enrollments
+	DeleteCadence(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error
+
+	// UnenrolledEntries returns the entries of open leads, across
+	// organizations, into the scope of an active cadence that were not
+	// enrolled yet
+	UnenrolledEntries(ctx context.Context, limit int) ([]LeadCadenceEntry, error)
+	// Enroll stores the enrollment with an activity per task, stopping the
+	// lead's other active enrollments. It reports false for an entry
+	// already enrolled.
+	Enroll(ctx context.Context, enrollment LeadCadenceEnrollment) (bool, error)
+	// SyncEnrollments closes the tasks whose activity was done, cancelled
+	// or deleted, then completes and stops enrollments, returning how many
+	// of each
+	SyncEnrollments(ctx context.Context, now time.Time) (completed int, stopped int, err error)
+	// SkipTask and StopEnrollment return ErrLeadCadenceClosed for a task
+	// that is not pending or an enrollment that is not active
+	SkipTask(ctx context.Context, orgID uuid.UUID, id uuid.UUID, skippedBy *uuid.UUID, at time.Time) (*LeadCadenceTask, error)
+	StopEnrollment(ctx context.Context, orgID uuid.UUID, id uuid.UUID, at time.Time) error
+
+	// FindEnrollments returns the lead's enrollments with their tasks,
+	// latest first
+	FindEnrollments(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]LeadCadenceEnrollment, error)
+	Performance(ctx context.Context, orgID uuid.UUID) ([]LeadCadencePerformance, error)
+}
+
+// LeadEmailRepository stores inbound mailboxes and the emails they attach
+// to leads
+type LeadEmailRepository interface {
+	CreateInbox(ctx context.Context, inbox LeadEmailInbox) (*LeadEmailInbox, error)
+	FindInbox(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*LeadEmailInbox, error)
+	// FindInboxByToken returns the active inbox with the webhook token
+	FindInboxByToken(ctx context.Context, token string) (*LeadEmailInbox, error)
+	ListInboxes(ctx context.Context, orgID uuid.UUID) ([]LeadEmailInbox, error)
+	UpdateInbox(ctx context.Context, inbox LeadEmailInbox) (*LeadEmailInbox, error)
+	DeleteInbox(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error
+
+	// FindMessage returns the message received with the Message-ID, or nil
+	FindMessage(ctx context.Context, orgID uuid.UUID, messageID string) (*LeadEmailMessage, error)
+	// FindReplied returns the most recent message among the Message-IDs
+	// whose lead was not deleted, or nil
+	FindReplied(ctx context.Context, orgID uuid.UUID, messageIDs []string) (*LeadEmailMessage, error)
+	// CreateMessage stores the message with the activity showing it on the
+	// lead's timeline, returning ErrLeadEmailDuplicate for a Message-ID
+	// already received
+	CreateMessage(ctx context.Context, msg LeadEmailMessage, activity Activity) (*LeadEmailMessage, error)
+	ListByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]LeadEmailMessage, error)
+}
+
+// LeadAuditRepository stores the field-level change history of leads
+type LeadAuditRepository interface {
+	// Record stores the entries of one update in one transaction
+	Record(ctx context.Context, entries []LeadAuditEntry) error
+	// FindByLead returns a lead's entries, most recent first
+	FindByLead(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]LeadAuditEntry, error)
+	Export(ctx context.Context, filter LeadAuditFilter) ([]LeadAuditEntry, error)
+}
+
+// SchedulingLinkRepository stores public scheduling links and the meetings
+// booked through them
+type SchedulingLinkRepository interface {
+	CreateLink(ctx context.Context, link SchedulingLink) (*SchedulingLink, error)
+	FindLink(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*SchedulingLink, error)
+	// FindLinkByToken returns the active link with the booking token
+	FindLinkByToken(ctx context.Context, token string) (*SchedulingLink, error)
+	ListLinks(ctx context.Context, orgID uuid.UUID) ([]SchedulingLink, error)
+	UpdateLink(ctx context.Context, link SchedulingLink) (*SchedulingLink, error)
+	DeleteLink(ctx context.Context, orgID uuid.UUID, id uuid.UUID) error
+
+	// CreateBooking stores the booking with the host's calendar event, named
+	// after the activity, and the meeting activity on the lead. It returns
+	// ErrSlotUnavailable when the host got another event within buffer of
+	// the booked time.
+	CreateBooking(ctx context.Context, booking LeadBooking, activity Activity, buffer time.Duration) (*LeadBooking, error)
+	// BookingCounts returns the number of bookings of each host of the link
+	BookingCounts(ctx context.Context, linkID uuid.UUID) (map[uuid.UUID]int, error)
+	ListBookings(ctx context.Context, orgID uuid.UUID, leadID uuid.UUID) ([]LeadBooking, error)
+}
+
 type LeadSourceRepository interface {
 	CRUDRepository[LeadSource, LeadSourceFilter]
 }
@@ -76,7 +414,69 @@ type ActivityRepository interface {
 	FindByLead(ctx context.Context, leadID uuid.UUID) ([]*Activity, error)
 }
 
+// AssignmentEngineRepository loads what assignment strategies choose from,
+// and records their choices
+type AssignmentEngineRepository interface {
+	// FindMatchingRule returns the highest priority active rule of the
+	// organization matching the conditions now, or ErrNoMatchingAssignmentRule
+	FindMatchingRule(ctx context.Context, orgID uuid.UUID, targetModel string, conditions map[string]interface{}) (*AssignmentRule, error)
+	// ListCandidateLoads returns the load of each user, in the given order,
+	// with defaults for users without one
+	ListCandidateLoads(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]UserAssignmentLoad, error)
+	ListTerritories(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]*Territory, error)
+	GetUserName(ctx context.Context, userID uuid.UUID) (string, error)
+	// RecordAssignment assigns the lead, records it in the history and moves
+	// the load to the new assignee in one transaction. It returns
+	// ErrAssignmentConflict when the rule's round robin position has moved.
+	RecordAssignment(ctx context.Context, record AssignmentRecord) error
+}
+
+// TeamCapacityRepository stores the assignment capacity of users and their
+// scheduled unavailability
+type TeamCapacityRepository interface {
+	// ListCapacities returns the capacity of the organization's active
+	// members, or of the given users among them, ordered by name
+	ListCapacities(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]UserCapacity, error)
+	// SaveCapacity stores the settings of the capacity, leaving the
+	// assignment counts alone
+	SaveCapacity(ctx context.Context, orgID uuid.UUID, capacity UserCapacity) error
+
+	CreateUnavailability(ctx context.Context, period UserUnavailability) (*UserUnavailability, error)
+	// ListUnavailability returns the periods of the users, every member when
+	// nil, that have not ended by the instant, in start order
+	ListUnavailability(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, endingAfter time.Time) ([]UserUnavailability, error)
+	// DeleteUnavailability returns ErrUserUnavailabilityNotFound when the
+	// period is not one of the user's
+	DeleteUnavailability(ctx context.Context, orgID, userID, id uuid.UUID) error
+}
+
+// CRMTagRepository stores organization tags and applies them to leads and
+// contacts
+type CRMTagRepository interface {
+	// Create returns ErrCRMTagExists when the organization has a tag of the
+	// name
+	Create(ctx context.Context, tag CRMTag) (*CRMTag, error)
+	// FindByID returns the tag with its usage counts, or ErrCRMTagNotFound
+	FindByID(ctx context.Context, orgID, id uuid.UUID) (*CRMTag, error)
+	// FindAll returns the tags matching the filter with their usage counts,
+	// ordered by name
+	FindAll(ctx context.Context, filter CRMTagFilter) ([]CRMTag, error)
+	Update(ctx context.Context, tag CRMTag) (*CRMTag, error)
+	// Delete removes the tag and strips it from the leads and contacts
+	// carrying it
+	Delete(ctx context.Context, orgID, id uuid.UUID) error
+	// MissingIDs returns the ids that are not tags of the organization
+	MissingIDs(ctx context.Context, orgID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error)
+	// AddToRecord adds the tags a lead or contact does not carry yet and
+	// returns the tags it carries, or ErrTaggedRecordNotFound
+	AddToRecord(ctx context.Context, orgID uuid.UUID, target CRMTagTarget, recordID uuid.UUID, tagIDs []uuid.UUID) ([]uuid.UUID, error)
+	// RemoveFromRecord removes the tag from a lead or contact and returns the
+	// tags it still carries, or ErrTaggedRecordNotFound
+	RemoveFromRecord(ctx context.Context, orgID uuid.UUID, target CRMTagTarget, recordID, tagID uuid.UUID) ([]uuid.UUID, error)
+}
+
 type AssignmentRuleRepository interface {
+	AssignmentEngineRepository
 	Create(ctx context.Context, rule AssignmentRule) (*AssignmentRule, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*AssignmentRule, error)
 	FindAll(ctx context.Context, limit, offset int) ([]AssignmentRule, error)
@@ -92,10 +492,7 @@ type AssignmentRuleRepository interface {
 	GetTerritory(ctx context.Context, id uuid.UUID) (*Territory, error)
 	UpdateTerritory(ctx context.Context, territory *Territory) error
 	DeleteTerritory(ctx context.Context, id uuid.UUID) error
-	ListTerritories(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]*Territory, error)
-	GetNextAssignee(ctx context.Context, targetModel string, conditions map[string]interface{}) (uuid.UUID, string, error)
 	GetAssignmentStatsByUser(ctx context.Context, orgID uuid.UUID, targetModel string) ([]*AssignmentStatsByUser, error)
 	GetAssignmentRuleEffectiveness(ctx context.Context, orgID uuid.UUID) ([]*AssignmentRuleEffectiveness, error)
-	AssignLead(ctx context.Context, leadID uuid.UUID, assigneeID uuid.UUID, reason string) error
 	GetLead(ctx context.Context, leadID uuid.UUID) (*Lead, error)
 }
@@ -68,7 +68,7 @@ type LeadCreateRequest struct {
 	DateClosed       *time.Time     `json:"date_closed,omitempty"`
 	DateDeadline     *time.Time     `json:"date_deadline,omitempty"`
 	Active           bool           `json:"active"`
-	Status           *string        `json:"status,omitempty"`
+	Status           *LeadStatus    `json:"status,omitempty"`
 	AssignedTo       *uuid.UUID     `json:"assigned_to,omitempty"`
 	WonStatus        *LeadWonStatus `json:"won_status,omitempty"`
 	LostReasonID     *uuid.UUID     `json:"lost_reason_id,omitempty"`
@@ -112,7 +112,7 @@ type LeadUpdateRequest struct {
 	DateClosed       *time.Time     `json:"date_closed,omitempty"`
 	DateDeadline     *time.Time     `json:"date_deadline,omitempty"`
 	Active           *bool          `json:"active,omitempty"`
-	Status           *string        `json:"status,omitempty"`
+	Status           *LeadStatus    `json:"status,omitempty"`
 	AssignedTo       *uuid.UUID     `json:"assigned_to,omitempty"`
 	WonStatus        *LeadWonStatus `json:"won_status,omitempty"`
 	LostReasonID     *uuid.UUID     `json:"lost_reason_id,omitempty"`
@@ -128,4 +128,10 @@ type LeadUpdateRequest struct {
 	Color            *int           `json:"color,omitempty"`
 	CustomFields     interface{}    `json:"custom_fields,omitempty"`
 	Metadata         interface{}    `json:"metadata,omitempty"`
+	// ProbabilityOverridden set to false drops the manual probability and
+	// reverts to the stage probability; setting Probability overrides it
+	ProbabilityOverridden *bool `json:"probability_overridden,omitempty"`
+	// Version is the version of the lead the changes were made to. The
+	// update is refused when the lead has been written since.
+	Version *int `json:"version,omitempty"`
 }
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -31,6 +33,7 @@ type SalesTeamFilter struct {
 	Code           *string
 	TeamLeaderID   *uuid.UUID
 	IsActive       *bool
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSchedulingLink is returned for link requests that fail validation
+	ErrInvalidSchedulingLink = errors.New("invalid scheduling link")
+	// ErrSchedulingLinkNotFound is returned for unknown or inactive booking tokens
+	ErrSchedulingLinkNotFound = errors.New("scheduling link not found")
+	// ErrInvalidBooking is returned for booking requests that fail validation
+	ErrInvalidBooking = errors.New("invalid booking")
+	// ErrSlotUnavailable is returned when the requested time is not, or no
+	// longer, free
+	ErrSlotUnavailable = errors.New("slot is not available")
+)
+
+// SchedulingLink is a public booking page for a user or a sales team,
+// reached with Token. Team links share the bookings among the members.
+type SchedulingLink struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	Name           string     `json:"name" db:"name"`
+	Token          string     `json:"token" db:"token"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	TeamID         *uuid.UUID `json:"team_id,omitempty" db:"team_id"`
+	// DurationMinutes is the meeting length and StepMinutes the spacing of
+	// the offered start times
+	DurationMinutes int `json:"duration_minutes" db:"duration_minutes"`
+	StepMinutes     int `json:"step_minutes" db:"step_minutes"`
+	// BufferMinutes is kept free before and after the hosts' other events
+	BufferMinutes    int `json:"buffer_minutes" db:"buffer_minutes"`
+	MinNoticeMinutes int `json:"min_notice_minutes" db:"min_notice_minutes"`
+	HorizonDays      int `json:"horizon_days" db:"horizon_days"`
+	// SourceID is the source of the leads created by bookings
+	SourceID *uuid.UUID `json:"source_id,omitempty" db:"source_id"`
+	Active   bool       `json:"active" db:"active"`
+	// BookingPath is the public booking page, relative to the API host. A
+	// lead query parameter with a lead ID attributes bookings to that lead.
+	BookingPath string     `json:"booking_path" db:"-"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// SchedulingLinkRequest creates or updates a link. On update, omitted
+// fields are left unchanged; a link is for either a user or a team.
+type SchedulingLinkRequest struct {
+	Name             *string    `json:"name,omitempty"`
+	UserID           *uuid.UUID `json:"user_id,omitempty"`
+	TeamID           *uuid.UUID `json:"team_id,omitempty"`
+	DurationMinutes  *int       `json:"duration_minutes,omitempty"`
+	StepMinutes      *int       `json:"step_minutes,omitempty"`
+	BufferMinutes    *int       `json:"buffer_minutes,omitempty"`
+	MinNoticeMinutes *int       `json:"min_notice_minutes,omitempty"`
+	HorizonDays      *int       `json:"horizon_days,omitempty"`
+	SourceID         *uuid.UUID `json:"source_id,omitempty"`
+	Active           *bool      `json:"active,omitempty"`
+	// RotateToken issues a new token, invalidating the old booking page
+	RotateToken bool `json:"rotate_token,omitempty"`
+}
+
+// PublicSchedulingLink is what the booking page shows prospects
+type PublicSchedulingLink struct {
+	Name            string `json:"name"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Timezone        string `json:"timezone"`
+}
+
+// SchedulingSlot is a start time at which at least one host is free
+type SchedulingSlot struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// LeadBookingRequest is a prospect's booking through a link
+type LeadBookingRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Notes    *string   `json:"notes,omitempty"`
+	// LeadID comes from the link's lead tracking parameter
+	LeadID *uuid.UUID `json:"-"`
+}
+
+// LeadBookingAttribution is how a booking was matched to its lead
+type LeadBookingAttribution string
+
+const (
+	// LeadBookingTracking used the lead tracking parameter of the link
+	LeadBookingTracking LeadBookingAttribution = "tracking"
+	// LeadBookingEmail matched the invitee's email to an open lead
+	LeadBookingEmail LeadBookingAttribution = "email"
+	// LeadBookingCreated created a lead for the invitee
+	LeadBookingCreated LeadBookingAttribution = "created"
+)
+
+// LeadBooking is a meeting booked through a link, with the host's calendar
+// event and the meeting activity on the lead
+type LeadBooking struct {
+	ID             uuid.UUID              `json:"id" db:"id"`
+	OrganizationID uuid.UUID              `json:"organization_id" db:"organization_id"`
+	LinkID         *uuid.UUID             `json:"link_id,omitempty" db:"link_id"`
+	LeadID         uuid.UUID              `json:"lead_id" db:"lead_id"`
+	HostUserID     uuid.UUID              `json:"host_user_id" db:"host_user_id"`
+	EventID        *uuid.UUID             `json:"event_id,omitempty" db:"event_id"`
+	ActivityID     *uuid.UUID             `json:"activity_id,omitempty" db:"activity_id"`
+	StartsAt       time.Time              `json:"starts_at" db:"starts_at"`
+	EndsAt         time.Time              `json:"ends_at" db:"ends_at"`
+	InviteeName    string                 `json:"invitee_name" db:"invitee_name"`
+	InviteeEmail   string                 `json:"invitee_email" db:"invitee_email"`
+	Notes          *string                `json:"notes,omitempty" db:"notes"`
+	Attribution    LeadBookingAttribution `json:"attribution" db:"attribution"`
+	CreatedAt      time.Time              `json:"created_at" db:"created_at"`
+}
+
+// LeadBookingConfirmation is returned to the prospect after booking
+type LeadBookingConfirmation struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
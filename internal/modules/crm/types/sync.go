@@ -0,0 +1,160 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSync is returned for sync requests that fail validation, such
+// as an unknown entity or a malformed cursor
+var ErrInvalidSync = errors.New("invalid sync request")
+
+// SyncEntity is a kind of record the mobile app keeps an offline copy of
+type SyncEntity string
+
+const (
+	SyncLeads      SyncEntity = "leads"
+	SyncContacts   SyncEntity = "contacts"
+	SyncActivities SyncEntity = "activities"
+)
+
+// SyncEntities lists the entities that sync, in the order a batch pulls them
+var SyncEntities = []SyncEntity{SyncLeads, SyncContacts, SyncActivities}
+
+// IsValid reports whether the entity syncs
+func (e SyncEntity) IsValid() bool {
+	switch e {
+	case SyncLeads, SyncContacts, SyncActivities:
+		return true
+	}
+	return false
+}
+
+// SyncMarker is a change to a record found past a cursor: the record was
+// written or deleted at ChangedAt
+type SyncMarker struct {
+	ID        uuid.UUID
+	ChangedAt time.Time
+	Deleted   bool
+}
+
+// SyncChange is a record written or deleted since the client's cursor.
+// Record holds the requested fields of a written record; deleted records
+// only carry their ID.
+type SyncChange struct {
+	ID        uuid.UUID              `json:"id"`
+	Deleted   bool                   `json:"deleted,omitempty"`
+	ChangedAt time.Time              `json:"changed_at"`
+	Record    map[string]interface{} `json:"record,omitempty"`
+}
+
+// SyncPage is a page of an entity's changes. Cursor is passed back to read
+// the changes after it; HasMore tells to do so right away.
+type SyncPage struct {
+	Entity  SyncEntity   `json:"entity"`
+	Changes []SyncChange `json:"changes"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
+
+// SyncPull reads an entity's changes since Cursor, empty for a first full
+// sync. Fields lists the record fields returned, all when empty.
+type SyncPull struct {
+	Cursor string   `json:"cursor,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// SyncAction is what an operation does to a record
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+)
+
+// IsValid reports whether the action is known
+func (a SyncAction) IsValid() bool {
+	switch a {
+	case SyncCreate, SyncUpdate, SyncDelete:
+		return true
+	}
+	return false
+}
+
+// SyncOp is a write the app made offline. ClientID is generated by the app
+// for each operation; an operation sent again, such as after a dropped
+// connection, is answered with its first outcome instead of being applied
+// twice.
+//
+// Updates and deletes name their record by ID or, for records created
+// offline whose ID the app does not know yet, by Ref, the ClientID of the
+// operation that created it. BaseUpdatedAt is the updated_at the app last
+// saw; when the record changed since, the operation is a conflict unless
+// Force is set.
+type SyncOp struct {
+	ClientID      uuid.UUID       `json:"client_id"`
+	Entity        SyncEntity      `json:"entity"`
+	Action        SyncAction      `json:"action"`
+	ID            *uuid.UUID      `json:"id,omitempty"`
+	Ref           *uuid.UUID      `json:"ref,omitempty"`
+	BaseUpdatedAt *time.Time      `json:"base_updated_at,omitempty"`
+	Force         bool            `json:"force,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+}
+
+// SyncOpStatus is the outcome of an operation
+type SyncOpStatus string
+
+const (
+	SyncApplied SyncOpStatus = "applied"
+	// SyncConflict operations were not applied as the record changed since
+	// the app last saw it; the result carries the record as it is now
+	SyncConflict SyncOpStatus = "conflict"
+	// SyncRejected operations failed validation or permission checks and
+	// will fail again if resent as they are
+	SyncRejected SyncOpStatus = "rejected"
+)
+
+// SyncOpResult is the outcome of an operation. Replayed is set when the
+// operation had already been applied and was not applied again.
+type SyncOpResult struct {
+	ClientID uuid.UUID              `json:"client_id"`
+	Status   SyncOpStatus           `json:"status"`
+	ID       *uuid.UUID             `json:"id,omitempty"`
+	Record   map[string]interface{} `json:"record,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Replayed bool                   `json:"replayed,omitempty"`
+}
+
+// SyncAppliedOp is an operation applied, kept so that it is not applied
+// again and so later operations can refer to the record it created
+type SyncAppliedOp struct {
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	ClientID       uuid.UUID  `json:"client_id" db:"client_id"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Entity         SyncEntity `json:"entity" db:"entity"`
+	Action         SyncAction `json:"action" db:"action"`
+	RecordID       uuid.UUID  `json:"record_id" db:"record_id"`
+	AppliedAt      time.Time  `json:"applied_at" db:"applied_at"`
+}
+
+// SyncRequest is one round trip of the app: its offline writes, applied in
+// order, then the changes of each entity it pulls. Limit bounds the
+// changes per entity.
+type SyncRequest struct {
+	Ops   []SyncOp                `json:"ops,omitempty"`
+	Pull  map[SyncEntity]SyncPull `json:"pull,omitempty"`
+	Limit int                     `json:"limit,omitempty"`
+}
+
+// SyncResponse answers a SyncRequest. Results carry the records as the
+// operations left them; pulls return them too once past the sync lag.
+type SyncResponse struct {
+	Results    []SyncOpResult          `json:"results"`
+	Changes    map[SyncEntity]SyncPage `json:"changes"`
+	ServerTime time.Time               `json:"server_time"`
+}
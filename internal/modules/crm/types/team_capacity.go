@@ -0,0 +1,99 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidTeamCapacity is returned for invalid capacity settings or
+	// unavailability periods
+	ErrInvalidTeamCapacity = errors.New("invalid team capacity")
+	// ErrUserUnavailabilityNotFound is returned when an unavailability period
+	// does not exist in the organization
+	ErrUserUnavailabilityNotFound = errors.New("user unavailability not found")
+)
+
+// UserUnavailability is a period, such as a vacation, during which a user
+// takes no automatic assignments
+type UserUnavailability struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	StartsAt       time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt         time.Time  `json:"ends_at" db:"ends_at"`
+	Reason         string     `json:"reason" db:"reason"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
+
+// InProgress reports whether the period covers the instant
+func (u UserUnavailability) InProgress(at time.Time) bool {
+	return !at.Before(u.StartsAt) && at.Before(u.EndsAt)
+}
+
+// UserUnavailabilityRequest schedules an unavailability period
+type UserUnavailabilityRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// UserCapacityUpdateRequest changes the assignment settings of a user for a
+// target model, leads by default. Omitted fields are left unchanged.
+// Switching a user back to available clears their unavailable_until unless
+// a new one is given.
+type UserCapacityUpdateRequest struct {
+	TargetModel      string     `json:"target_model,omitempty"`
+	MaxCapacity      *int       `json:"max_capacity,omitempty"`
+	Weight           *int       `json:"weight,omitempty"`
+	IsAvailable      *bool      `json:"is_available,omitempty"`
+	UnavailableUntil *time.Time `json:"unavailable_until,omitempty"`
+}
+
+// UserCapacity is the assignment settings and load of a user for a target
+// model. Users without a load row are idle, available, unlimited and of
+// weight 1.
+type UserCapacity struct {
+	UserID            uuid.UUID  `json:"user_id"`
+	Name              string     `json:"name"`
+	Email             string     `json:"email"`
+	TargetModel       string     `json:"target_model"`
+	ActiveAssignments int        `json:"active_assignments"`
+	TotalAssignments  int        `json:"total_assignments"`
+	LastAssignedAt    *time.Time `json:"last_assigned_at,omitempty"`
+	// MaxCapacity is the most active assignments the user takes, 0 is unlimited
+	MaxCapacity      int        `json:"max_capacity"`
+	Weight           int        `json:"weight"`
+	IsAvailable      bool       `json:"is_available"`
+	UnavailableUntil *time.Time `json:"unavailable_until,omitempty"`
+}
+
+// TeamMemberWorkload is a user's load against their capacity on the team
+// dashboard
+type TeamMemberWorkload struct {
+	UserCapacity
+	// Utilization is the active assignments as a percentage of the capacity,
+	// nil when the capacity is unlimited
+	Utilization  *float64 `json:"utilization,omitempty"`
+	OverCapacity bool     `json:"over_capacity"`
+	// AvailableNow is whether automatic assignment can pick the user now
+	AvailableNow   bool                `json:"available_now"`
+	CurrentAbsence *UserUnavailability `json:"current_absence,omitempty"`
+	NextAbsence    *UserUnavailability `json:"next_absence,omitempty"`
+}
+
+// TeamWorkload is the team dashboard: the load of every member against their
+// capacity
+type TeamWorkload struct {
+	TeamID            *uuid.UUID `json:"team_id,omitempty"`
+	TargetModel       string     `json:"target_model"`
+	ActiveAssignments int        `json:"active_assignments"`
+	// Capacity sums the capacity of available members with a limit
+	Capacity         int                  `json:"capacity"`
+	AvailableMembers int                  `json:"available_members"`
+	Members          []TeamMemberWorkload `json:"members"`
+	GeneratedAt      time.Time            `json:"generated_at"`
+}
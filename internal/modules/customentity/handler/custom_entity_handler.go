@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CustomEntityHandler handles HTTP requests for custom entity definitions and
+// the CRUD endpoints generated for each entity
+type CustomEntityHandler struct {
+	service *service.CustomEntityService
+}
+
+func NewCustomEntityHandler(service *service.CustomEntityService) *CustomEntityHandler {
+	return &CustomEntityHandler{service: service}
+}
+
+func (h *CustomEntityHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/custom-entities", h.ListEntities)
+	router.POST("/api/v1/custom-entities", h.CreateEntity)
+	router.GET("/api/v1/custom-entities/:entity", h.GetEntity)
+	router.PUT("/api/v1/custom-entities/:entity", h.UpdateEntity)
+	router.DELETE("/api/v1/custom-entities/:entity", h.DeleteEntity)
+	router.GET("/api/v1/custom-entities/:entity/records", h.ListRecords)
+	router.POST("/api/v1/custom-entities/:entity/records", h.CreateRecord)
+	router.GET("/api/v1/custom-entities/:entity/records/:id", h.GetRecord)
+	router.PUT("/api/v1/custom-entities/:entity/records/:id", h.UpdateRecord)
+	router.DELETE("/api/v1/custom-entities/:entity/records/:id", h.DeleteRecord)
+}
+
+// ListEntities handles GET /api/v1/custom-entities
+func (h *CustomEntityHandler) ListEntities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	defs, err := h.service.ListEntities(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, defs)
+}
+
+// CreateEntity handles POST /api/v1/custom-entities
+func (h *CustomEntityHandler) CreateEntity(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.EntityDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.service.CreateEntity(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, def)
+}
+
+// GetEntity handles GET /api/v1/custom-entities/:entity
+func (h *CustomEntityHandler) GetEntity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	def, err := h.service.GetEntity(r.Context(), authCtx.OrganizationID, ps.ByName("entity"))
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// UpdateEntity handles PUT /api/v1/custom-entities/:entity
+func (h *CustomEntityHandler) UpdateEntity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.EntityDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	def, err := h.service.UpdateEntity(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), req)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// DeleteEntity handles DELETE /api/v1/custom-entities/:entity
+func (h *CustomEntityHandler) DeleteEntity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteEntity(r.Context(), authCtx.OrganizationID, ps.ByName("entity")); err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRecords handles GET /api/v1/custom-entities/:entity/records?q=&limit=&offset=&<field>[__gte|__lte]=
+func (h *CustomEntityHandler) ListRecords(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	params := types.RecordListParams{
+		Query:   query.Get("q"),
+		Filters: make(map[string]string),
+	}
+	for key, values := range query {
+		switch key {
+		case "q":
+		case "limit", "offset":
+			n, err := strconv.Atoi(values[0])
+			if err != nil {
+				http.Error(w, "Invalid "+key, http.StatusBadRequest)
+				return
+			}
+			if key == "limit" {
+				params.Limit = n
+			} else {
+				params.Offset = n
+			}
+		default:
+			params.Filters[key] = values[0]
+		}
+	}
+
+	records, err := h.service.ListRecords(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), params)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+	if records == nil {
+		records = []types.Record{}
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// CreateRecord handles POST /api/v1/custom-entities/:entity/records
+func (h *CustomEntityHandler) CreateRecord(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.RecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.service.CreateRecord(r.Context(), authCtx.OrganizationID, authCtx.UserID, ps.ByName("entity"), req)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, record)
+}
+
+// GetRecord handles GET /api/v1/custom-entities/:entity/records/:id
+func (h *CustomEntityHandler) GetRecord(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.service.GetRecord(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), id)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// UpdateRecord handles PUT /api/v1/custom-entities/:entity/records/:id
+func (h *CustomEntityHandler) UpdateRecord(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RecordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.service.UpdateRecord(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), id, req)
+	if err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// DeleteRecord handles DELETE /api/v1/custom-entities/:entity/records/:id
+func (h *CustomEntityHandler) DeleteRecord(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteRecord(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), id); err != nil {
+		writeCustomEntityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCustomEntityError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
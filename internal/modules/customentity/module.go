@@ -0,0 +1,71 @@
+package customentity
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CustomEntityModule represents the custom entity (low-code object) module
+type CustomEntityModule struct {
+	customEntityHandler *handler.CustomEntityHandler
+	logger              *slog.Logger
+}
+
+// NewCustomEntityModule creates a new custom entity module
+func NewCustomEntityModule() *CustomEntityModule {
+	return &CustomEntityModule{}
+}
+
+// Name returns the module name
+func (m *CustomEntityModule) Name() string {
+	return "customentity"
+}
+
+// Init initializes the custom entity module
+func (m *CustomEntityModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "customentity")
+	m.logger.Info("Initializing custom entity module")
+
+	// Create repositories
+	customEntityRepo := repository.NewCustomEntityRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	customEntityService := service.NewCustomEntityService(customEntityRepo, authAdapter, m.logger)
+	if deps.EventBus != nil {
+		customEntityService.SetEventPublisher(deps.EventBus)
+	}
+
+	// Create handlers
+	m.customEntityHandler = handler.NewCustomEntityHandler(customEntityService)
+
+	m.logger.Info("Custom entity module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers custom entity module routes
+func (m *CustomEntityModule) RegisterRoutes(router interface{}) {
+	if m.customEntityHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.customEntityHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the custom entity module
+func (m *CustomEntityModule) RegisterEventHandlers(bus interface{}) {
+	// Record changes are published from the service; nothing to consume
+}
+
+// Health checks the health of the custom entity module
+func (m *CustomEntityModule) Health() error {
+	return nil
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when an entity definition or record does not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// CustomEntityRepo defines the interface for custom entity repository operations
+type CustomEntityRepo interface {
+	ListDefinitions(ctx context.Context, orgID uuid.UUID) ([]types.EntityDefinition, error)
+	FindDefinition(ctx context.Context, orgID uuid.UUID, name string) (*types.EntityDefinition, error)
+	SaveDefinition(ctx context.Context, def types.EntityDefinition) (*types.EntityDefinition, error)
+	DeleteDefinition(ctx context.Context, orgID, id uuid.UUID) error
+	ListRecords(ctx context.Context, filter types.RecordFilter) ([]types.Record, error)
+	FindRecord(ctx context.Context, orgID, entityID, id uuid.UUID) (*types.Record, error)
+	SaveRecord(ctx context.Context, record types.Record) (*types.Record, error)
+	DeleteRecord(ctx context.Context, orgID, entityID, id uuid.UUID) error
+	RelatedRecordExists(ctx context.Context, orgID uuid.UUID, target string, id uuid.UUID) (bool, error)
+}
+
+// CustomEntityRepository persists custom entity definitions and their records
+type CustomEntityRepository struct {
+	db *sql.DB
+}
+
+// Ensure CustomEntityRepository implements CustomEntityRepo interface
+var _ CustomEntityRepo = &CustomEntityRepository{}
+
+func NewCustomEntityRepository(db *sql.DB) *CustomEntityRepository {
+	return &CustomEntityRepository{db: db}
+}
+
+const definitionColumns = `id, organization_id, name, label, plural_label, description, title_field, searchable, fields, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDefinition(row rowScanner) (*types.EntityDefinition, error) {
+	var def types.EntityDefinition
+	var fieldsJSON []byte
+	err := row.Scan(
+		&def.ID, &def.OrganizationID, &def.Name, &def.Label, &def.PluralLabel, &def.Description,
+		&def.TitleField, &def.Searchable, &fieldsJSON, &def.CreatedAt, &def.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(fieldsJSON, &def.Fields); err != nil {
+		return nil, fmt.Errorf("invalid entity fields: %w", err)
+	}
+	return &def, nil
+}
+
+func (r *CustomEntityRepository) ListDefinitions(ctx context.Context, orgID uuid.UUID) ([]types.EntityDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM custom_entity_definitions WHERE organization_id = $1 ORDER BY label`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom entities: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []types.EntityDefinition
+	for rows.Next() {
+		def, err := scanDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom entity: %w", err)
+		}
+		defs = append(defs, *def)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list custom entities: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *CustomEntityRepository) FindDefinition(ctx context.Context, orgID uuid.UUID, name string) (*types.EntityDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM custom_entity_definitions WHERE organization_id = $1 AND name = $2`
+
+	def, err := scanDefinition(r.db.QueryRowContext(ctx, query, orgID, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("custom entity %q %w", name, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find custom entity: %w", err)
+	}
+	return def, nil
+}
+
+func (r *CustomEntityRepository) SaveDefinition(ctx context.Context, def types.EntityDefinition) (*types.EntityDefinition, error) {
+	fieldsJSON, err := json.Marshal(def.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entity fields: %w", err)
+	}
+
+	query := `
+		INSERT INTO custom_entity_definitions (id, organization_id, name, label, plural_label, description, title_field, searchable, fields, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			label = EXCLUDED.label,
+			plural_label = EXCLUDED.plural_label,
+			description = EXCLUDED.description,
+			title_field = EXCLUDED.title_field,
+			searchable = EXCLUDED.searchable,
+			fields = EXCLUDED.fields,
+			updated_at = NOW()
+		WHERE custom_entity_definitions.organization_id = EXCLUDED.organization_id
+		RETURNING ` + definitionColumns
+
+	saved, err := scanDefinition(r.db.QueryRowContext(ctx, query,
+		def.ID, def.OrganizationID, def.Name, def.Label, def.PluralLabel, def.Description,
+		def.TitleField, def.Searchable, fieldsJSON,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save custom entity: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *CustomEntityRepository) DeleteDefinition(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM custom_entity_definitions WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom entity: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete custom entity: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("custom entity %w", ErrNotFound)
+	}
+	return nil
+}
+
+const recordColumns = `r.id, r.organization_id, r.entity_id, d.name, r.title, r.data, r.created_by, r.created_at, r.updated_at`
+
+func scanRecord(row rowScanner) (*types.Record, error) {
+	var record types.Record
+	var dataJSON []byte
+	err := row.Scan(
+		&record.ID, &record.OrganizationID, &record.EntityID, &record.Entity, &record.Title,
+		&dataJSON, &record.CreatedBy, &record.CreatedAt, &record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(dataJSON, &record.Data); err != nil {
+		return nil, fmt.Errorf("invalid record data: %w", err)
+	}
+	return &record, nil
+}
+
+// ListRecords returns records of an entity matching the filter, newest first.
+// Equality conditions use jsonb containment so they are served by the GIN
+// index on data; range conditions compare the extracted value.
+func (r *CustomEntityRepository) ListRecords(ctx context.Context, filter types.RecordFilter) ([]types.Record, error) {
+	where := []string{"r.organization_id = $1", "r.entity_id = $2", "r.deleted_at IS NULL"}
+	args := []interface{}{filter.OrganizationID, filter.EntityID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		args = append(args, database.LikePattern(filter.Query, database.MatchModeContains))
+		where = append(where, database.ILike("r.title", len(args)))
+	}
+
+	for _, c := range filter.Conditions {
+		switch c.Operator {
+		case types.FilterEq:
+			contains, err := json.Marshal(map[string]interface{}{c.Field: c.Value})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode filter on %s: %w", c.Field, err)
+			}
+			where = append(where, fmt.Sprintf("r.data @> %s::jsonb", arg(string(contains))))
+		case types.FilterGte, types.FilterLte:
+			op := ">="
+			if c.Operator == types.FilterLte {
+				op = "<="
+			}
+			field := arg(c.Field)
+			if c.Type == types.FieldTypeNumber {
+				where = append(where, fmt.Sprintf("jsonb_typeof(r.data->%[1]s) = 'number' AND (r.data->>%[1]s)::numeric %[2]s %[3]s", field, op, arg(c.Value)))
+			} else {
+				// Dates and datetimes are stored normalized, so they compare as text
+				where = append(where, fmt.Sprintf("r.data->>%s %s %s", field, op, arg(c.Value)))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported filter operator: %s", c.Operator)
+		}
+	}
+
+	query := `SELECT ` + recordColumns + `
+		FROM custom_entity_records r
+		JOIN custom_entity_definitions d ON d.id = r.entity_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY r.created_at DESC, r.id
+		LIMIT ` + arg(filter.Limit) + ` OFFSET ` + arg(filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []types.Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom record: %w", err)
+		}
+		records = append(records, *record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list custom records: %w", err)
+	}
+	return records, nil
+}
+
+func (r *CustomEntityRepository) FindRecord(ctx context.Context, orgID, entityID, id uuid.UUID) (*types.Record, error) {
+	query := `SELECT ` + recordColumns + `
+		FROM custom_entity_records r
+		JOIN custom_entity_definitions d ON d.id = r.entity_id
+		WHERE r.organization_id = $1 AND r.entity_id = $2 AND r.id = $3 AND r.deleted_at IS NULL`
+
+	record, err := scanRecord(r.db.QueryRowContext(ctx, query, orgID, entityID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("custom record %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find custom record: %w", err)
+	}
+	return record, nil
+}
+
+func (r *CustomEntityRepository) SaveRecord(ctx context.Context, record types.Record) (*types.Record, error) {
+	dataJSON, err := json.Marshal(record.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record data: %w", err)
+	}
+
+	query := `
+		WITH saved AS (
+			INSERT INTO custom_entity_records (id, organization_id, entity_id, title, data, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+			ON CONFLICT (id) DO UPDATE SET
+				title = EXCLUDED.title,
+				data = EXCLUDED.data,
+				updated_at = NOW()
+			WHERE custom_entity_records.organization_id = EXCLUDED.organization_id
+				AND custom_entity_records.deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT ` + recordColumns + `
+		FROM saved r
+		JOIN custom_entity_definitions d ON d.id = r.entity_id`
+
+	saved, err := scanRecord(r.db.QueryRowContext(ctx, query,
+		record.ID, record.OrganizationID, record.EntityID, record.Title, dataJSON, record.CreatedBy,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("custom record %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to save custom record: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *CustomEntityRepository) DeleteRecord(ctx context.Context, orgID, entityID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE custom_entity_records SET deleted_at = NOW(), updated_at = NOW()
+		WHERE organization_id = $1 AND entity_id = $2 AND id = $3 AND deleted_at IS NULL
+	`, orgID, entityID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom record: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete custom record: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("custom record %w", ErrNotFound)
+	}
+	return nil
+}
+
+// RelatedRecordExists reports whether a relation target record exists in the organization
+func (r *CustomEntityRepository) RelatedRecordExists(ctx context.Context, orgID uuid.UUID, target string, id uuid.UUID) (bool, error) {
+	var query string
+	args := []interface{}{orgID, id}
+
+	if entity, ok := strings.CutPrefix(target, types.CustomEntityTargetPrefix); ok {
+		query = `
+			SELECT EXISTS (
+				SELECT 1 FROM custom_entity_records r
+				JOIN custom_entity_definitions d ON d.id = r.entity_id
+				WHERE r.organization_id = $1 AND r.id = $2 AND r.deleted_at IS NULL AND d.name = $3
+			)`
+		args = append(args, entity)
+	} else {
+		table, ok := types.CoreRelationTargets[target]
+		if !ok {
+			return false, fmt.Errorf("unsupported relation target: %s", target)
+		}
+		query = `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL)`
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check related record: %w", err)
+	}
+	return exists, nil
+}
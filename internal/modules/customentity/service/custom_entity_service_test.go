@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customentity/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCustomEntityRepo struct {
+	defs    map[string]types.EntityDefinition
+	records map[uuid.UUID]types.Record
+	related map[uuid.UUID]string
+	filters []types.RecordFilter
+}
+
+func newFakeCustomEntityRepo() *fakeCustomEntityRepo {
+	return &fakeCustomEntityRepo{
+		defs:    make(map[string]types.EntityDefinition),
+		records: make(map[uuid.UUID]types.Record),
+		related: make(map[uuid.UUID]string),
+	}
+}
+
+func (f *fakeCustomEntityRepo) ListDefinitions(ctx context.Context, orgID uuid.UUID) ([]types.EntityDefinition, error) {
+	return nil, nil
+}
+
+func (f *fakeCustomEntityRepo) FindDefinition(ctx context.Context, orgID uuid.UUID, name string) (*types.EntityDefinition, error) {
+	def, ok := f.defs[name]
+	if !ok || def.OrganizationID != orgID {
+		return nil, fmt.Errorf("custom entity %q %w", name, repository.ErrNotFound)
+	}
+	return &def, nil
+}
+
+func (f *fakeCustomEntityRepo) SaveDefinition(ctx context.Context, def types.EntityDefinition) (*types.EntityDefinition, error) {
+	f.defs[def.Name] = def
+	return &def, nil
+}
+
+func (f *fakeCustomEntityRepo) DeleteDefinition(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeCustomEntityRepo) ListRecords(ctx context.Context, filter types.RecordFilter) ([]types.Record, error) {
+	f.filters = append(f.filters, filter)
+	return nil, nil
+}
+
+func (f *fakeCustomEntityRepo) FindRecord(ctx context.Context, orgID, entityID, id uuid.UUID) (*types.Record, error) {
+	record, ok := f.records[id]
+	if !ok || record.EntityID != entityID {
+		return nil, fmt.Errorf("custom record %w", repository.ErrNotFound)
+	}
+	return &record, nil
+}
+
+func (f *fakeCustomEntityRepo) SaveRecord(ctx context.Context, record types.Record) (*types.Record, error) {
+	for _, def := range f.defs {
+		if def.ID == record.EntityID {
+			record.Entity = def.Name
+		}
+	}
+	f.records[record.ID] = record
+	return &record, nil
+}
+
+func (f *fakeCustomEntityRepo) DeleteRecord(ctx context.Context, orgID, entityID, id uuid.UUID) error {
+	delete(f.records, id)
+	return nil
+}
+
+func (f *fakeCustomEntityRepo) RelatedRecordExists(ctx context.Context, orgID uuid.UUID, target string, id uuid.UUID) (bool, error) {
+	return f.related[id] == target, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type recordedEvents struct {
+	types    []string
+	payloads []interface{}
+}
+
+func (r *recordedEvents) Publish(ctx context.Context, eventType string, payload interface{}) error {
+	r.types = append(r.types, eventType)
+	r.payloads = append(r.payloads, payload)
+	return nil
+}
+
+func equipmentRequest() types.EntityDefinitionRequest {
+	return types.EntityDefinitionRequest{
+		Name:       "equipment",
+		Label:      "Equipment",
+		TitleField: "name",
+		Fields: []types.FieldDefinition{
+			{Name: "name", Label: "Name", Type: types.FieldTypeText, Required: true},
+			{Name: "status", Label: "Status", Type: types.FieldTypeSelect, Options: []string{"active", "retired"}},
+			{Name: "cost", Label: "Cost", Type: types.FieldTypeNumber},
+			{Name: "purchased_on", Label: "Purchased on", Type: types.FieldTypeDate},
+			{Name: "customer", Label: "Customer", Type: types.FieldTypeRelation, Target: "contact"},
+		},
+	}
+}
+
+func TestCreateEntityValidation(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	svc := NewCustomEntityService(newFakeCustomEntityRepo(), allowAll{}, nil)
+
+	invalid := map[string]func(*types.EntityDefinitionRequest){
+		"bad name":          func(r *types.EntityDefinitionRequest) { r.Name = "Equipment!" },
+		"no label":          func(r *types.EntityDefinitionRequest) { r.Label = "" },
+		"title not text":    func(r *types.EntityDefinitionRequest) { r.TitleField = "cost" },
+		"duplicate field":   func(r *types.EntityDefinitionRequest) { r.Fields = append(r.Fields, r.Fields[0]) },
+		"reserved suffix":   func(r *types.EntityDefinitionRequest) { r.Fields[2].Name = "cost__gte" },
+		"select no options": func(r *types.EntityDefinitionRequest) { r.Fields[1].Options = nil },
+		"unknown target":    func(r *types.EntityDefinitionRequest) { r.Fields[4].Target = "warehouse" },
+		"missing custom":    func(r *types.EntityDefinitionRequest) { r.Fields[4].Target = "custom:projects" },
+	}
+	for name, mutate := range invalid {
+		req := equipmentRequest()
+		mutate(&req)
+		_, err := svc.CreateEntity(ctx, orgID, req)
+		assert.True(t, errors.Is(err, ErrInvalid), name)
+	}
+
+	def, err := svc.CreateEntity(ctx, orgID, equipmentRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "Equipment", def.PluralLabel)
+	assert.True(t, def.Searchable)
+
+	_, err = svc.CreateEntity(ctx, orgID, equipmentRequest())
+	assert.True(t, errors.Is(err, ErrInvalid), "names are unique per organization")
+
+	// Existing fields keep their type
+	update := equipmentRequest()
+	update.Fields[2].Type = types.FieldTypeText
+	_, err = svc.UpdateEntity(ctx, orgID, "equipment", update)
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
+
+func TestRecordLifecycle(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	repo := newFakeCustomEntityRepo()
+	events := &recordedEvents{}
+	svc := NewCustomEntityService(repo, allowAll{}, nil)
+	svc.SetEventPublisher(events)
+
+	_, err := svc.CreateEntity(ctx, orgID, equipmentRequest())
+	require.NoError(t, err)
+
+	contactID := uuid.New()
+	repo.related[contactID] = "contact"
+
+	_, err = svc.CreateRecord(ctx, orgID, userID, "equipment", types.RecordRequest{Data: map[string]interface{}{"status": "active"}})
+	assert.True(t, errors.Is(err, ErrInvalid), "name is required")
+
+	_, err = svc.CreateRecord(ctx, orgID, userID, "equipment", types.RecordRequest{Data: map[string]interface{}{"name": "Forklift", "status": "broken"}})
+	assert.True(t, errors.Is(err, ErrInvalid), "status must be an option")
+
+	_, err = svc.CreateRecord(ctx, orgID, userID, "equipment", types.RecordRequest{Data: map[string]interface{}{"name": "Forklift", "customer": uuid.NewString()}})
+	assert.True(t, errors.Is(err, ErrInvalid), "related contact must exist")
+
+	_, err = svc.CreateRecord(ctx, orgID, userID, "equipment", types.RecordRequest{Data: map[string]interface{}{"name": "Forklift", "serial": "X1"}})
+	assert.True(t, errors.Is(err, ErrInvalid), "unknown fields are rejected")
+
+	record, err := svc.CreateRecord(ctx, orgID, userID, "equipment", types.RecordRequest{Data: map[string]interface{}{
+		"name":         "Forklift",
+		"cost":         12500.0,
+		"purchased_on": "2025-03-01",
+		"customer":     contactID.String(),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Forklift", record.Title)
+	assert.Equal(t, &userID, record.CreatedBy)
+	assert.Equal(t, []string{EventRecordCreated}, events.types)
+	assert.Equal(t, "equipment", events.payloads[0].(map[string]interface{})["entity"])
+
+	updated, err := svc.UpdateRecord(ctx, orgID, "equipment", record.ID, types.RecordRequest{Data: map[string]interface{}{
+		"name": "Forklift #2",
+		"cost": nil,
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Forklift #2", updated.Title)
+	assert.NotContains(t, updated.Data, "cost")
+	assert.Equal(t, "2025-03-01", updated.Data["purchased_on"])
+
+	_, err = svc.UpdateRecord(ctx, orgID, "equipment", record.ID, types.RecordRequest{Data: map[string]interface{}{"name": nil}})
+	assert.True(t, errors.Is(err, ErrInvalid), "required fields cannot be cleared")
+
+	require.NoError(t, svc.DeleteRecord(ctx, orgID, "equipment", record.ID))
+	assert.Equal(t, []string{EventRecordCreated, EventRecordUpdated, EventRecordDeleted}, events.types)
+}
+
+func TestListRecordsParsesFilters(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakeCustomEntityRepo()
+	svc := NewCustomEntityService(repo, allowAll{}, nil)
+
+	_, err := svc.CreateEntity(ctx, orgID, equipmentRequest())
+	require.NoError(t, err)
+
+	_, err = svc.ListRecords(ctx, orgID, "equipment", types.RecordListParams{
+		Filters: map[string]string{"cost__gte": "1000", "purchased_on__lte": "2025-12-31", "status": "active"},
+		Limit:   1000,
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.filters, 1)
+	filter := repo.filters[0]
+	assert.Equal(t, MaxRecordLimit, filter.Limit)
+
+	conditions := make(map[string]types.FilterCondition)
+	for _, c := range filter.Conditions {
+		conditions[c.Field] = c
+	}
+	assert.Equal(t, types.FilterCondition{Field: "cost", Type: types.FieldTypeNumber, Operator: types.FilterGte, Value: 1000.0}, conditions["cost"])
+	assert.Equal(t, types.FilterLte, conditions["purchased_on"].Operator)
+	assert.Equal(t, "active", conditions["status"].Value)
+
+	invalid := []map[string]string{
+		{"serial": "X1"},
+		{"cost": "cheap"},
+		{"status__gte": "active"},
+		{"status": "broken"},
+	}
+	for _, filters := range invalid {
+		_, err := svc.ListRecords(ctx, orgID, "equipment", types.RecordListParams{Filters: filters})
+		assert.True(t, errors.Is(err, ErrInvalid), "%v", filters)
+	}
+}
+
+func TestCoerceDateTimeNormalizesToUTC(t *testing.T) {
+	field := types.FieldDefinition{Name: "due_at", Type: types.FieldTypeDateTime}
+
+	v, err := coerceValue(field, "2025-06-01T09:30:00+02:00")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-06-01T07:30:00Z", v)
+
+	_, err = coerceValue(field, "tomorrow")
+	assert.True(t, errors.Is(err, ErrInvalid))
+}
@@ -0,0 +1,150 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FieldType is the data type of a custom entity field
+type FieldType string
+
+const (
+	FieldTypeText     FieldType = "text"
+	FieldTypeNumber   FieldType = "number"
+	FieldTypeBoolean  FieldType = "boolean"
+	FieldTypeDate     FieldType = "date"
+	FieldTypeDateTime FieldType = "datetime"
+	FieldTypeSelect   FieldType = "select"
+	FieldTypeRelation FieldType = "relation"
+)
+
+// IsValid reports whether the field type is supported
+func (t FieldType) IsValid() bool {
+	switch t {
+	case FieldTypeText, FieldTypeNumber, FieldTypeBoolean, FieldTypeDate, FieldTypeDateTime, FieldTypeSelect, FieldTypeRelation:
+		return true
+	}
+	return false
+}
+
+// CustomEntityTargetPrefix marks a relation target that is another custom
+// entity, e.g. "custom:equipment"
+const CustomEntityTargetPrefix = "custom:"
+
+// CoreRelationTargets maps the core record types a relation field may point
+// at to the table holding them
+var CoreRelationTargets = map[string]string{
+	"contact":        "contacts",
+	"lead":           "leads",
+	"product":        "products",
+	"sales_order":    "sales_orders",
+	"invoice":        "invoices",
+	"purchase_order": "purchase_orders",
+}
+
+// FieldDefinition describes one field of a custom entity
+type FieldDefinition struct {
+	Name     string    `json:"name"`
+	Label    string    `json:"label"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required,omitempty"`
+	// Options lists the allowed values of a select field
+	Options []string `json:"options,omitempty"`
+	// Target is the record type a relation field points at: a core type such
+	// as "contact", or "custom:<entity>" for another custom entity
+	Target string `json:"target,omitempty"`
+}
+
+// EntityDefinition is the metadata of a customer-defined object
+type EntityDefinition struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id"`
+	Name           string            `json:"name"`
+	Label          string            `json:"label"`
+	PluralLabel    string            `json:"plural_label"`
+	Description    *string           `json:"description,omitempty"`
+	TitleField     string            `json:"title_field"`
+	Searchable     bool              `json:"searchable"`
+	Fields         []FieldDefinition `json:"fields"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Field returns the field with the given name
+func (d *EntityDefinition) Field(name string) (FieldDefinition, bool) {
+	for _, f := range d.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FieldDefinition{}, false
+}
+
+// EntityDefinitionRequest creates or replaces an entity definition. Name is
+// only read on create; the URL identifies the entity on update.
+type EntityDefinitionRequest struct {
+	Name        string            `json:"name"`
+	Label       string            `json:"label"`
+	PluralLabel string            `json:"plural_label"`
+	Description *string           `json:"description,omitempty"`
+	TitleField  string            `json:"title_field"`
+	Searchable  *bool             `json:"searchable,omitempty"`
+	Fields      []FieldDefinition `json:"fields"`
+}
+
+// Record is one instance of a custom entity
+type Record struct {
+	ID             uuid.UUID              `json:"id"`
+	OrganizationID uuid.UUID              `json:"organization_id"`
+	EntityID       uuid.UUID              `json:"entity_id"`
+	Entity         string                 `json:"entity"`
+	Title          string                 `json:"title"`
+	Data           map[string]interface{} `json:"data"`
+	CreatedBy      *uuid.UUID             `json:"created_by,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// RecordRequest carries field values for a create or update. On update only
+// the given fields change and a null value clears a field.
+type RecordRequest struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// FilterOperator compares a record field with a filter value
+type FilterOperator string
+
+const (
+	FilterEq  FilterOperator = "eq"
+	FilterGte FilterOperator = "gte"
+	FilterLte FilterOperator = "lte"
+)
+
+// FilterCondition restricts a record list on one field
+type FilterCondition struct {
+	Field    string
+	Type     FieldType
+	Operator FilterOperator
+	Value    interface{}
+}
+
+// RecordFilter represents the criteria for listing records of an entity
+type RecordFilter struct {
+	OrganizationID uuid.UUID
+	EntityID       uuid.UUID
+	Query          string
+	Conditions     []FilterCondition
+	Limit          int
+	Offset         int
+}
+
+// RecordListParams are the raw list options of the auto-generated list
+// endpoint. Filters maps a field name, optionally suffixed with __gte or
+// __lte, to the value to compare with.
+type RecordListParams struct {
+	Query   string
+	Filters map[string]string
+	Limit   int
+	Offset  int
+}
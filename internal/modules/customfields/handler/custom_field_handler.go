@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CustomFieldHandler handles HTTP requests for custom field definitions
+type CustomFieldHandler struct {
+	service *service.CustomFieldService
+}
+
+func NewCustomFieldHandler(service *service.CustomFieldService) *CustomFieldHandler {
+	return &CustomFieldHandler{service: service}
+}
+
+func (h *CustomFieldHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/settings/custom-fields/:entity", h.ListFields)
+	router.GET("/api/v1/settings/custom-fields/:entity/:name", h.GetField)
+	router.PUT("/api/v1/settings/custom-fields/:entity/:name", h.SaveField)
+	router.DELETE("/api/v1/settings/custom-fields/:entity/:name", h.DeleteField)
+}
+
+// ListFields handles GET /api/v1/settings/custom-fields/:entity
+func (h *CustomFieldHandler) ListFields(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	defs, err := h.service.ListFields(r.Context(), authCtx.OrganizationID, ps.ByName("entity"))
+	if err != nil {
+		writeCustomFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, defs)
+}
+
+// GetField handles GET /api/v1/settings/custom-fields/:entity/:name
+func (h *CustomFieldHandler) GetField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	def, err := h.service.GetField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), ps.ByName("name"))
+	if err != nil {
+		writeCustomFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// SaveField handles PUT /api/v1/settings/custom-fields/:entity/:name
+func (h *CustomFieldHandler) SaveField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.FieldDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Name = ps.ByName("name")
+
+	def, err := h.service.SaveField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), req)
+	if err != nil {
+		writeCustomFieldError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+// DeleteField handles DELETE /api/v1/settings/custom-fields/:entity/:name
+func (h *CustomFieldHandler) DeleteField(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteField(r.Context(), authCtx.OrganizationID, ps.ByName("entity"), ps.ByName("name")); err != nil {
+		writeCustomFieldError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeCustomFieldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, customfields.ErrInvalidDefinition):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
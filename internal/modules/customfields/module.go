@@ -0,0 +1,68 @@
+package customfields
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CustomFieldsModule represents the custom field definition module
+type CustomFieldsModule struct {
+	customFieldHandler *handler.CustomFieldHandler
+	logger             *slog.Logger
+}
+
+// NewCustomFieldsModule creates a new custom fields module
+func NewCustomFieldsModule() *CustomFieldsModule {
+	return &CustomFieldsModule{}
+}
+
+// Name returns the module name
+func (m *CustomFieldsModule) Name() string {
+	return "customfields"
+}
+
+// Init initializes the custom fields module
+func (m *CustomFieldsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "customfields")
+	m.logger.Info("Initializing custom fields module")
+
+	// Create repositories
+	customFieldRepo := repository.NewCustomFieldRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	customFieldService := service.NewCustomFieldService(customFieldRepo, authAdapter, m.logger)
+
+	// Create handlers
+	m.customFieldHandler = handler.NewCustomFieldHandler(customFieldService)
+
+	m.logger.Info("Custom fields module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers custom fields module routes
+func (m *CustomFieldsModule) RegisterRoutes(router interface{}) {
+	if m.customFieldHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.customFieldHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the custom fields module
+func (m *CustomFieldsModule) RegisterEventHandlers(bus interface{}) {
+	// Definitions are read by entity services; nothing to consume
+}
+
+// Health checks the health of the custom fields module
+func (m *CustomFieldsModule) Health() error {
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a custom field does not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// CustomFieldRepo defines the interface for custom field repository operations
+type CustomFieldRepo interface {
+	ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error)
+	FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error)
+	SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error)
+	DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error
+}
+
+// CustomFieldRepository persists custom field definitions
+type CustomFieldRepository struct {
+	db *sql.DB
+}
+
+// Ensure CustomFieldRepository implements CustomFieldRepo interface
+var _ CustomFieldRepo = &CustomFieldRepository{}
+
+func NewCustomFieldRepository(db *sql.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+const definitionColumns = `id, organization_id, entity, name, label, type, required, options, position, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDefinition(row rowScanner) (*types.FieldDefinition, error) {
+	var def types.FieldDefinition
+	err := row.Scan(
+		&def.ID, &def.OrganizationID, &def.Entity, &def.Name, &def.Label, &def.Type,
+		&def.Required, pq.Array(&def.Options), &def.Position, &def.CreatedAt, &def.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+func (r *CustomFieldRepository) ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM custom_field_definitions WHERE organization_id = $1 AND entity = $2 ORDER BY position, name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []types.FieldDefinition
+	for rows.Next() {
+		def, err := scanDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		defs = append(defs, *def)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list custom fields: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *CustomFieldRepository) FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	query := `SELECT ` + definitionColumns + ` FROM custom_field_definitions WHERE organization_id = $1 AND entity = $2 AND name = $3`
+
+	def, err := scanDefinition(r.db.QueryRowContext(ctx, query, orgID, entity, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("custom field %s.%s %w", entity, name, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find custom field: %w", err)
+	}
+	return def, nil
+}
+
+// SaveDefinition inserts the definition or replaces the organization's field
+// of the same entity and name
+func (r *CustomFieldRepository) SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error) {
+	options := def.Options
+	if options == nil {
+		options = []string{}
+	}
+
+	query := `
+		INSERT INTO custom_field_definitions (id, organization_id, entity, name, label, type, required, options, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (organization_id, entity, name) DO UPDATE SET
+			label = EXCLUDED.label,
+			type = EXCLUDED.type,
+			required = EXCLUDED.required,
+			options = EXCLUDED.options,
+			position = EXCLUDED.position,
+			updated_at = NOW()
+		RETURNING ` + definitionColumns
+
+	saved, err := scanDefinition(r.db.QueryRowContext(ctx, query,
+		def.ID, def.OrganizationID, def.Entity, def.Name, def.Label, def.Type, def.Required, pq.Array(options), def.Position,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save custom field: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *CustomFieldRepository) DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM custom_field_definitions WHERE organization_id = $1 AND entity = $2 AND name = $3`,
+		orgID, entity, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("custom field %s.%s %w", entity, name, ErrNotFound)
+	}
+	return nil
+}
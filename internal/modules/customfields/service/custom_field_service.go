@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+
+	"github.com/google/uuid"
+)
+
+// AuthService defines the permission check used by the custom field service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// CustomFieldService manages the custom field definitions that entity
+// services validate custom_fields values against
+type CustomFieldService struct {
+	repo        repository.CustomFieldRepo
+	authService AuthService
+	logger      *slog.Logger
+}
+
+func NewCustomFieldService(repo repository.CustomFieldRepo, authService AuthService, logger *slog.Logger) *CustomFieldService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CustomFieldService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+// ListFields returns the organization's custom fields of an entity in form order
+func (s *CustomFieldService) ListFields(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "custom_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateEntity(entity); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDefinitions(ctx, orgID, entity)
+}
+
+// GetField returns a custom field by entity and name
+func (s *CustomFieldService) GetField(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "custom_fields:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDefinition(ctx, orgID, entity, name)
+}
+
+// SaveField creates the custom field or replaces the one with the same name.
+// A field keeps its type once defined so values already stored stay valid.
+// Making a field required or narrowing its options only applies to later
+// writes.
+func (s *CustomFieldService) SaveField(ctx context.Context, orgID uuid.UUID, entity string, req types.FieldDefinitionRequest) (*types.FieldDefinition, error) {
+	if err := s.authService.CheckPermission(ctx, "custom_fields:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateEntity(entity); err != nil {
+		return nil, err
+	}
+
+	def := types.FieldDefinition{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Entity:         entity,
+		Name:           strings.TrimSpace(req.Name),
+		Label:          strings.TrimSpace(req.Label),
+		Type:           req.Type,
+		Required:       req.Required,
+		Options:        req.Options,
+		Position:       req.Position,
+	}
+	if def.Label == "" {
+		def.Label = def.Name
+	}
+	if err := def.Definition().Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListDefinitions(ctx, orgID, entity)
+	if err != nil {
+		return nil, err
+	}
+	replacing := false
+	for _, e := range existing {
+		if e.Name != def.Name {
+			continue
+		}
+		if e.Type != def.Type {
+			return nil, fmt.Errorf("%w: field %q cannot change type from %s", customfields.ErrInvalidDefinition, def.Name, e.Type)
+		}
+		replacing = true
+		break
+	}
+	if !replacing && len(existing) >= customfields.MaxFieldsPerEntity {
+		return nil, fmt.Errorf("%w: %s may have at most %d custom fields", customfields.ErrInvalidDefinition, entity, customfields.MaxFieldsPerEntity)
+	}
+
+	saved, err := s.repo.SaveDefinition(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("custom field saved", "entity", entity, "name", saved.Name, "type", saved.Type)
+	return saved, nil
+}
+
+// DeleteField removes a custom field. Stored values are left in place and
+// are rejected by the next write that includes them.
+func (s *CustomFieldService) DeleteField(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	if err := s.authService.CheckPermission(ctx, "custom_fields:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteDefinition(ctx, orgID, entity, name)
+}
+
+func validateEntity(entity string) error {
+	if _, ok := customfields.Entities[entity]; !ok {
+		return fmt.Errorf("%w: entity %q does not support custom fields", customfields.ErrInvalidDefinition, entity)
+	}
+	return nil
+}
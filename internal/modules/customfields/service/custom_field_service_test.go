@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/customfields/types"
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCustomFieldRepo struct {
+	defs map[string]types.FieldDefinition
+}
+
+func newFakeCustomFieldRepo() *fakeCustomFieldRepo {
+	return &fakeCustomFieldRepo{defs: make(map[string]types.FieldDefinition)}
+}
+
+func (f *fakeCustomFieldRepo) ListDefinitions(ctx context.Context, orgID uuid.UUID, entity string) ([]types.FieldDefinition, error) {
+	var defs []types.FieldDefinition
+	for _, def := range f.defs {
+		if def.OrganizationID == orgID && def.Entity == entity {
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+func (f *fakeCustomFieldRepo) FindDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) (*types.FieldDefinition, error) {
+	def, ok := f.defs[entity+"."+name]
+	if !ok || def.OrganizationID != orgID {
+		return nil, fmt.Errorf("custom field %s.%s %w", entity, name, repository.ErrNotFound)
+	}
+	return &def, nil
+}
+
+func (f *fakeCustomFieldRepo) SaveDefinition(ctx context.Context, def types.FieldDefinition) (*types.FieldDefinition, error) {
+	f.defs[def.Entity+"."+def.Name] = def
+	return &def, nil
+}
+
+func (f *fakeCustomFieldRepo) DeleteDefinition(ctx context.Context, orgID uuid.UUID, entity, name string) error {
+	delete(f.defs, entity+"."+name)
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type denyAll struct{}
+
+func (denyAll) CheckPermission(ctx context.Context, permission string) error {
+	return errors.New("forbidden")
+}
+
+func industry() types.FieldDefinitionRequest {
+	return types.FieldDefinitionRequest{
+		Name:    "industry",
+		Type:    customfields.TypeSelect,
+		Options: []string{"retail", "finance"},
+	}
+}
+
+func TestSaveFieldValidatesDefinition(t *testing.T) {
+	repo := newFakeCustomFieldRepo()
+	svc := NewCustomFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	def, err := svc.SaveField(context.Background(), orgID, "lead", industry())
+	require.NoError(t, err)
+	assert.Equal(t, "industry", def.Label, "label defaults to the name")
+
+	_, err = svc.SaveField(context.Background(), orgID, "lead", types.FieldDefinitionRequest{
+		Name: "seats", Type: customfields.TypeNumber, Options: []string{"1"},
+	})
+	assert.ErrorIs(t, err, customfields.ErrInvalidDefinition)
+
+	_, err = svc.SaveField(context.Background(), orgID, "invoice", industry())
+	assert.ErrorIs(t, err, customfields.ErrInvalidDefinition)
+
+	// Stored values keep matching their field's type
+	_, err = svc.SaveField(context.Background(), orgID, "lead", types.FieldDefinitionRequest{Name: "industry", Type: customfields.TypeText})
+	assert.ErrorIs(t, err, customfields.ErrInvalidDefinition)
+
+	widened := industry()
+	widened.Options = append(widened.Options, "energy")
+	widened.Required = true
+	def, err = svc.SaveField(context.Background(), orgID, "lead", widened)
+	require.NoError(t, err)
+	assert.True(t, def.Required)
+
+	_, err = NewCustomFieldService(repo, denyAll{}, nil).SaveField(context.Background(), orgID, "lead", industry())
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, customfields.ErrInvalidDefinition)
+}
+
+func TestSaveFieldEnforcesLimit(t *testing.T) {
+	repo := newFakeCustomFieldRepo()
+	svc := NewCustomFieldService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	for i := 0; i < customfields.MaxFieldsPerEntity; i++ {
+		repo.defs[fmt.Sprintf("lead.f%d", i)] = types.FieldDefinition{
+			OrganizationID: orgID, Entity: "lead", Name: fmt.Sprintf("f%d", i), Type: customfields.TypeText,
+		}
+	}
+
+	_, err := svc.SaveField(context.Background(), orgID, "lead", industry())
+	assert.ErrorIs(t, err, customfields.ErrInvalidDefinition)
+
+	// Replacing an existing field does not count against the limit
+	_, err = svc.SaveField(context.Background(), orgID, "lead", types.FieldDefinitionRequest{
+		Name: "f0", Label: "First", Type: customfields.TypeText,
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+
+	"github.com/google/uuid"
+)
+
+// FieldDefinition is a custom field an organization has defined on an entity
+type FieldDefinition struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id"`
+	Entity         string            `json:"entity"`
+	Name           string            `json:"name"`
+	Label          string            `json:"label"`
+	Type           customfields.Type `json:"type"`
+	Required       bool              `json:"required"`
+	Options        []string          `json:"options,omitempty"`
+	Position       int               `json:"position"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Definition returns the definition in the form the customfields package validates
+func (d FieldDefinition) Definition() customfields.Definition {
+	return customfields.Definition{
+		Entity:   d.Entity,
+		Name:     d.Name,
+		Label:    d.Label,
+		Type:     d.Type,
+		Required: d.Required,
+		Options:  d.Options,
+	}
+}
+
+// FieldDefinitionRequest creates or replaces a custom field. The URL names
+// the entity and the field.
+type FieldDefinitionRequest struct {
+	Name     string            `json:"name"`
+	Label    string            `json:"label"`
+	Type     customfields.Type `json:"type"`
+	Required bool              `json:"required"`
+	Options  []string          `json:"options,omitempty"`
+	Position int               `json:"position"`
+}
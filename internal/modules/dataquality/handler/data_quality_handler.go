@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DataQualityHandler handles HTTP requests for data quality checks, the
+// hygiene dashboard and the work queue
+type DataQualityHandler struct {
+	service *service.DataQualityService
+}
+
+func NewDataQualityHandler(service *service.DataQualityService) *DataQualityHandler {
+	return &DataQualityHandler{service: service}
+}
+
+func (h *DataQualityHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/data-quality/checks", h.ListChecks)
+	router.PUT("/api/v1/data-quality/checks/:check", h.UpdateCheck)
+	router.POST("/api/v1/data-quality/evaluate", h.Evaluate)
+	router.GET("/api/v1/data-quality/hygiene", h.GetHygiene)
+	router.GET("/api/v1/data-quality/issues", h.ListIssues)
+	router.POST("/api/v1/data-quality/issues/:id/dismiss", h.DismissIssue)
+}
+
+// writeDataQualityError maps service errors to HTTP statuses
+func writeDataQualityError(w http.ResponseWriter, err error, status int) {
+	if strings.HasPrefix(err.Error(), "permission denied") {
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// ListChecks handles GET /api/v1/data-quality/checks
+func (h *DataQualityHandler) ListChecks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	checks, err := h.service.ListChecks(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeDataQualityError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(checks)
+}
+
+// UpdateCheck handles PUT /api/v1/data-quality/checks/:check
+func (h *DataQualityHandler) UpdateCheck(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	key := types.CheckKey(ps.ByName("check"))
+	if _, ok := types.LookupCheck(key); !ok {
+		http.Error(w, "Invalid check", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CheckSettingUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setting, err := h.service.UpdateCheck(r.Context(), authCtx.OrganizationID, authCtx.UserID, key, req)
+	if err != nil {
+		writeDataQualityError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setting)
+}
+
+// Evaluate handles POST /api/v1/data-quality/evaluate
+func (h *DataQualityHandler) Evaluate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := h.service.Evaluate(r.Context(), authCtx.OrganizationID)
+	if err != nil && runs == nil {
+		writeDataQualityError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Partial failures are reported per check in the run list
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetHygiene handles GET /api/v1/data-quality/hygiene
+func (h *DataQualityHandler) GetHygiene(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetHygiene(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeDataQualityError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListIssues handles GET /api/v1/data-quality/issues?module=&check=&status=&limit=&offset=
+func (h *DataQualityHandler) ListIssues(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.IssueFilter{
+		Module:   query.Get("module"),
+		CheckKey: types.CheckKey(query.Get("check")),
+		Status:   types.IssueStatus(query.Get("status")),
+	}
+	for param, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := query.Get(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	issues, err := h.service.ListIssues(r.Context(), authCtx.OrganizationID, filter)
+	if err != nil {
+		writeDataQualityError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issues)
+}
+
+// DismissIssue handles POST /api/v1/data-quality/issues/:id/dismiss
+func (h *DataQualityHandler) DismissIssue(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	issueID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid issue ID", http.StatusBadRequest)
+		return
+	}
+
+	issue, err := h.service.DismissIssue(r.Context(), authCtx.OrganizationID, authCtx.UserID, issueID)
+	if err != nil {
+		writeDataQualityError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issue)
+}
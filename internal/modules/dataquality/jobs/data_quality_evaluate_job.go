@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeDataQualityEvaluate = "data_quality.evaluate"
+
+// DataQualityEvaluateJobHandler handles queued data quality evaluation jobs
+type DataQualityEvaluateJobHandler struct {
+	dataQualityService *service.DataQualityService
+}
+
+func NewDataQualityEvaluateJobHandler(dataQualityService *service.DataQualityService) *DataQualityEvaluateJobHandler {
+	return &DataQualityEvaluateJobHandler{
+		dataQualityService: dataQualityService,
+	}
+}
+
+// Handle processes a data quality evaluation job
+func (h *DataQualityEvaluateJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.dataQualityService.EvaluateAll(ctx); err != nil {
+		return fmt.Errorf("failed to evaluate data quality checks: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DataQualityEvaluateJobHandler) JobType() string {
+	return JobTypeDataQualityEvaluate
+}
+
+// Scheduler runs data quality evaluation on a fixed interval
+type Scheduler struct {
+	handler     *DataQualityEvaluateJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DataQualityEvaluateJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// NextRun returns when the next scheduled evaluation is due. Runs are
+// aligned to multiples of the interval, so every instance agrees on it.
+func (s *Scheduler) NextRun() time.Time {
+	return time.Now().Truncate(s.interval).Add(s.interval)
+}
+
+// Start runs evaluation every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeDataQualityEvaluate, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		s.logger.Info("Running scheduled data quality evaluation")
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeDataQualityEvaluate, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled data quality evaluation failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule data quality evaluation", "error", err)
+	}
+}
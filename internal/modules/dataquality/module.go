@@ -0,0 +1,77 @@
+package dataquality
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DataQualityModule represents the data quality module
+type DataQualityModule struct {
+	dataQualityService *service.DataQualityService
+	dataQualityHandler *handler.DataQualityHandler
+	scheduler          *jobs.Scheduler
+	logger             *slog.Logger
+}
+
+// NewDataQualityModule creates a new data quality module
+func NewDataQualityModule() *DataQualityModule {
+	return &DataQualityModule{}
+}
+
+// Name returns the module name
+func (m *DataQualityModule) Name() string {
+	return "dataquality"
+}
+
+// Init initializes the data quality module and starts the nightly evaluation
+func (m *DataQualityModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "dataquality")
+	m.logger.Info("Initializing data quality module")
+
+	// Create repositories
+	dataQualityRepo := repository.NewDataQualityRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.dataQualityService = service.NewDataQualityService(dataQualityRepo, authAdapter, m.logger)
+
+	// Create scheduled evaluation
+	evaluateJobHandler := jobs.NewDataQualityEvaluateJobHandler(m.dataQualityService)
+	m.scheduler = jobs.NewScheduler(evaluateJobHandler, service.RunInterval, deps.Scheduler, m.logger)
+	m.dataQualityService.SetNextRunFunc(m.scheduler.NextRun)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.dataQualityHandler = handler.NewDataQualityHandler(m.dataQualityService)
+
+	m.logger.Info("Data quality module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers data quality module routes
+func (m *DataQualityModule) RegisterRoutes(router interface{}) {
+	if m.dataQualityHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.dataQualityHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the data quality module
+func (m *DataQualityModule) RegisterEventHandlers(bus interface{}) {
+	// Evaluation is schedule driven; no events needed
+}
+
+// Health checks the health of the data quality module
+func (m *DataQualityModule) Health() error {
+	return nil
+}
@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/types"
+
+	"github.com/google/uuid"
+)
+
+// DataQualityRepo defines the interface for data quality repository operations
+type DataQualityRepo interface {
+	ListSettings(ctx context.Context, orgID uuid.UUID) ([]types.CheckSetting, error)
+	UpsertSetting(ctx context.Context, setting types.CheckSetting) (*types.CheckSetting, error)
+	Evaluate(ctx context.Context, orgID uuid.UUID, check types.Check, cutoff, now time.Time) (types.Evaluation, error)
+	CreateRun(ctx context.Context, run types.CheckRun) error
+	LatestRuns(ctx context.Context, orgID uuid.UUID) ([]types.CheckRun, error)
+	ListIssues(ctx context.Context, filter types.IssueFilter) ([]types.Issue, error)
+	DismissIssue(ctx context.Context, orgID, issueID, dismissedBy uuid.UUID) (*types.Issue, error)
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// DataQualityRepository persists check settings, runs and issues and evaluates checks
+type DataQualityRepository struct {
+	db *sql.DB
+}
+
+// Ensure DataQualityRepository implements DataQualityRepo interface
+var _ DataQualityRepo = &DataQualityRepository{}
+
+func NewDataQualityRepository(db *sql.DB) *DataQualityRepository {
+	return &DataQualityRepository{db: db}
+}
+
+// checkSources maps each check to the records it looks at. from is aliased
+// t and scope narrows it to the records the check covers; offending picks
+// those failing it. since is when a record started offending, compared with
+// the cutoff of checks with a threshold. label and details describe the
+// record in the work queue; details is a JSON object of strings.
+var checkSources = map[types.CheckKey]struct {
+	from      string
+	scope     string
+	offending string
+	since     string
+	label     string
+	details   string
+}{
+	types.CheckLeadsWithoutOwner: {
+		from:      "leads t",
+		scope:     "t.deleted_at IS NULL AND COALESCE(t.active, true) AND t.status IN ('new', 'in_progress')",
+		offending: "t.assigned_to IS NULL",
+		since: `COALESCE((SELECT MAX(h.assigned_at) FROM assignment_history h
+			WHERE h.target_model = 'leads' AND h.target_id = t.id), t.created_at)`,
+		label:   "t.name",
+		details: "jsonb_build_object('stage_id', t.stage_id::text, 'team_id', t.team_id::text)",
+	},
+	types.CheckContactsWithoutEmail: {
+		from:      "contacts t",
+		scope:     "t.deleted_at IS NULL",
+		offending: "NULLIF(BTRIM(t.email), '') IS NULL",
+		label:     "COALESCE(t.display_name, t.name)",
+		details:   "jsonb_build_object('phone', COALESCE(t.phone, t.mobile))",
+	},
+	types.CheckStalledShipments: {
+		from:      "delivery_shipments t",
+		scope:     "t.deleted_at IS NULL AND t.status IN ('scheduled', 'in_transit')",
+		offending: "true",
+		since:     "COALESCE(t.last_event_at, t.departed_at, t.updated_at)",
+		label:     "COALESCE(t.tracking_number, t.id::text)",
+		details:   "jsonb_build_object('status', t.status, 'carrier_name', t.carrier_name, 'last_event_at', t.last_event_at::text)",
+	},
+	types.CheckNegativeStock: {
+		from:      "stock_quants t JOIN stock_locations l ON l.id = t.location_id JOIN products p ON p.id = t.product_id",
+		scope:     "l.usage = 'internal'",
+		offending: "t.quantity < 0",
+		label:     "p.name || ' @ ' || l.name",
+		details:   "jsonb_build_object('product_id', t.product_id::text, 'location_id', t.location_id::text, 'quantity', t.quantity::text)",
+	},
+}
+
+func (r *DataQualityRepository) ListSettings(ctx context.Context, orgID uuid.UUID) ([]types.CheckSetting, error) {
+	query := `
+		SELECT id, organization_id, check_key, enabled, threshold_days, created_at, updated_at, updated_by
+		FROM data_quality_settings
+		WHERE organization_id = $1
+		ORDER BY check_key
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data quality settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []types.CheckSetting
+	for rows.Next() {
+		var s types.CheckSetting
+		if err := rows.Scan(&s.ID, &s.OrganizationID, &s.CheckKey, &s.Enabled, &s.ThresholdDays, &s.CreatedAt, &s.UpdatedAt, &s.UpdatedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan data quality setting: %w", err)
+		}
+		settings = append(settings, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during data quality setting iteration: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (r *DataQualityRepository) UpsertSetting(ctx context.Context, setting types.CheckSetting) (*types.CheckSetting, error) {
+	if setting.ID == uuid.Nil {
+		setting.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO data_quality_settings (id, organization_id, check_key, enabled, threshold_days, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW(), $6)
+		ON CONFLICT (organization_id, check_key) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			threshold_days = EXCLUDED.threshold_days,
+			updated_at = NOW(),
+			updated_by = EXCLUDED.updated_by
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		setting.ID, setting.OrganizationID, setting.CheckKey, setting.Enabled, setting.ThresholdDays, setting.UpdatedBy,
+	).Scan(&setting.ID, &setting.CreatedAt, &setting.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save data quality setting: %w", err)
+	}
+
+	return &setting, nil
+}
+
+// Evaluate runs a check over an organization's records in one transaction.
+// Offending records open an issue, or reopen their resolved one, and open
+// issues of records that pass the check again are resolved. Dismissed issues
+// stay dismissed.
+func (r *DataQualityRepository) Evaluate(ctx context.Context, orgID uuid.UUID, check types.Check, cutoff, now time.Time) (types.Evaluation, error) {
+	var eval types.Evaluation
+	src, ok := checkSources[check.Key]
+	if !ok {
+		return eval, fmt.Errorf("unknown check: %s", check.Key)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return eval, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE t.organization_id = $1 AND %s`, src.from, src.scope)
+	if err := tx.QueryRowContext(ctx, countQuery, orgID).Scan(&eval.Checked); err != nil {
+		return eval, fmt.Errorf("failed to count records checked by %s: %w", check.Key, err)
+	}
+
+	args := []interface{}{orgID, check.Key, check.Module, check.RecordType, now}
+	predicate := src.offending
+	if src.since != "" {
+		args = append(args, cutoff)
+		predicate += fmt.Sprintf(" AND %s <= $%d", src.since, len(args))
+	}
+
+	flagQuery := fmt.Sprintf(`
+		INSERT INTO data_quality_issues (
+			organization_id, check_key, module, record_type, record_id, record_label, details,
+			status, first_seen_at, last_seen_at
+		)
+		SELECT $1, $2::varchar, $3::varchar, $4::varchar, t.id, COALESCE(%s, ''), jsonb_strip_nulls(%s),
+			'open', $5::timestamptz, $5::timestamptz
+		FROM %s
+		WHERE t.organization_id = $1 AND %s AND %s
+		ON CONFLICT (organization_id, check_key, record_id) DO UPDATE SET
+			record_label = EXCLUDED.record_label,
+			details = EXCLUDED.details,
+			last_seen_at = EXCLUDED.last_seen_at,
+			status = CASE WHEN data_quality_issues.status = 'dismissed' THEN 'dismissed' ELSE 'open' END,
+			resolved_at = NULL
+	`, src.label, src.details, src.from, src.scope, predicate)
+	if _, err := tx.ExecContext(ctx, flagQuery, args...); err != nil {
+		return eval, fmt.Errorf("failed to flag records failing %s: %w", check.Key, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE data_quality_issues
+		SET status = 'resolved', resolved_at = $3
+		WHERE organization_id = $1 AND check_key = $2 AND status = 'open' AND last_seen_at < $3
+	`, orgID, check.Key, now)
+	if err != nil {
+		return eval, fmt.Errorf("failed to resolve records passing %s: %w", check.Key, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'open'),
+			COUNT(*) FILTER (WHERE status = 'dismissed' AND last_seen_at = $3)
+		FROM data_quality_issues
+		WHERE organization_id = $1 AND check_key = $2
+	`, orgID, check.Key, now).Scan(&eval.Offending, &eval.Dismissed)
+	if err != nil {
+		return eval, fmt.Errorf("failed to count records failing %s: %w", check.Key, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eval, fmt.Errorf("failed to commit %s evaluation: %w", check.Key, err)
+	}
+
+	return eval, nil
+}
+
+func (r *DataQualityRepository) CreateRun(ctx context.Context, run types.CheckRun) error {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO data_quality_runs (
+			id, organization_id, check_key, module, checked_count, offending_count, dismissed_count,
+			status, error, started_at, finished_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		run.ID, run.OrganizationID, run.CheckKey, run.Module, run.CheckedCount, run.OffendingCount, run.DismissedCount,
+		run.Status, run.Error, run.StartedAt, run.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record data quality run: %w", err)
+	}
+
+	return nil
+}
+
+// LatestRuns returns the latest completed run of every check evaluated in
+// the organization
+func (r *DataQualityRepository) LatestRuns(ctx context.Context, orgID uuid.UUID) ([]types.CheckRun, error) {
+	query := `
+		SELECT DISTINCT ON (check_key)
+			id, organization_id, check_key, module, checked_count, offending_count, dismissed_count,
+			status, error, started_at, finished_at
+		FROM data_quality_runs
+		WHERE organization_id = $1 AND status = 'completed'
+		ORDER BY check_key, started_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data quality runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []types.CheckRun
+	for rows.Next() {
+		var run types.CheckRun
+		if err := rows.Scan(&run.ID, &run.OrganizationID, &run.CheckKey, &run.Module, &run.CheckedCount,
+			&run.OffendingCount, &run.DismissedCount, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan data quality run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during data quality run iteration: %w", err)
+	}
+
+	return runs, nil
+}
+
+const issueColumns = `id, organization_id, check_key, module, record_type, record_id, record_label, details,
+	status, first_seen_at, last_seen_at, resolved_at, dismissed_at, dismissed_by`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIssue(row rowScanner) (types.Issue, error) {
+	var issue types.Issue
+	var details []byte
+	err := row.Scan(&issue.ID, &issue.OrganizationID, &issue.CheckKey, &issue.Module, &issue.RecordType,
+		&issue.RecordID, &issue.RecordLabel, &details, &issue.Status, &issue.FirstSeenAt, &issue.LastSeenAt,
+		&issue.ResolvedAt, &issue.DismissedAt, &issue.DismissedBy)
+	if err != nil {
+		return issue, err
+	}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &issue.Details); err != nil {
+			return issue, fmt.Errorf("failed to decode issue details: %w", err)
+		}
+	}
+	return issue, nil
+}
+
+// ListIssues returns the work queue, oldest issues first
+func (r *DataQualityRepository) ListIssues(ctx context.Context, filter types.IssueFilter) ([]types.Issue, error) {
+	args := []interface{}{filter.OrganizationID, filter.Status}
+	where := "organization_id = $1 AND status = $2"
+
+	if filter.Module != "" {
+		args = append(args, filter.Module)
+		where += fmt.Sprintf(" AND module = $%d", len(args))
+	}
+	if filter.CheckKey != "" {
+		args = append(args, filter.CheckKey)
+		where += fmt.Sprintf(" AND check_key = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM data_quality_issues
+		WHERE %s
+		ORDER BY first_seen_at, id
+		LIMIT $%d OFFSET $%d
+	`, issueColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data quality issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := []types.Issue{}
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data quality issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during data quality issue iteration: %w", err)
+	}
+
+	return issues, nil
+}
+
+// DismissIssue takes an open issue off the work queue for good
+func (r *DataQualityRepository) DismissIssue(ctx context.Context, orgID, issueID, dismissedBy uuid.UUID) (*types.Issue, error) {
+	query := fmt.Sprintf(`
+		UPDATE data_quality_issues
+		SET status = 'dismissed', dismissed_at = NOW(), dismissed_by = $3
+		WHERE id = $1 AND organization_id = $2 AND status = 'open'
+		RETURNING %s
+	`, issueColumns)
+
+	issue, err := scanIssue(r.db.QueryRowContext(ctx, query, issueID, orgID, dismissedBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("data quality issue not found or not open")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dismiss data quality issue: %w", err)
+	}
+
+	return &issue, nil
+}
+
+func (r *DataQualityRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during organization iteration: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RunInterval is how often scheduled evaluation runs
+	RunInterval = 24 * time.Hour
+	// DefaultIssueLimit is the work queue page size when none is requested
+	DefaultIssueLimit = 50
+	// MaxIssueLimit is the largest work queue page served
+	MaxIssueLimit = 200
+)
+
+// AuthService defines the permission check used by the data quality service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// DataQualityService configures and evaluates data quality checks and serves
+// the hygiene dashboard and the work queue of offending records
+type DataQualityService struct {
+	repo        repository.DataQualityRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+	nextRunAt   func() time.Time
+}
+
+func NewDataQualityService(repo repository.DataQualityRepo, authService AuthService, logger *slog.Logger) *DataQualityService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &DataQualityService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+	s.nextRunAt = func() time.Time { return s.now().Add(RunInterval) }
+	return s
+}
+
+// SetNextRunFunc lets the scheduler report when the next evaluation is due
+func (s *DataQualityService) SetNextRunFunc(fn func() time.Time) {
+	s.nextRunAt = fn
+}
+
+// ResolveSettings merges organization overrides with the default settings.
+// Every check is returned exactly once, in Checks order.
+func ResolveSettings(orgID uuid.UUID, overrides []types.CheckSetting) []types.CheckConfig {
+	byKey := make(map[types.CheckKey]types.CheckSetting, len(overrides))
+	for _, s := range overrides {
+		byKey[s.CheckKey] = s
+	}
+
+	configs := make([]types.CheckConfig, 0, len(types.Checks))
+	for _, check := range types.Checks {
+		setting, ok := byKey[check.Key]
+		if !ok {
+			setting = types.CheckSetting{
+				OrganizationID: orgID,
+				CheckKey:       check.Key,
+				Enabled:        true,
+				IsDefault:      true,
+			}
+		}
+		if check.HasThreshold() && setting.ThresholdDays == nil {
+			setting.ThresholdDays = check.DefaultThresholdDays
+		}
+		configs = append(configs, types.CheckConfig{Check: check, Setting: setting})
+	}
+	return configs
+}
+
+// ListChecks returns every check with its configuration in the organization
+func (s *DataQualityService) ListChecks(ctx context.Context, orgID uuid.UUID) ([]types.CheckConfig, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.resolveSettings(ctx, orgID)
+}
+
+func (s *DataQualityService) resolveSettings(ctx context.Context, orgID uuid.UUID) ([]types.CheckConfig, error) {
+	overrides, err := s.repo.ListSettings(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data quality settings: %w", err)
+	}
+	return ResolveSettings(orgID, overrides), nil
+}
+
+// UpdateCheck configures one check. Omitted fields keep their current value.
+func (s *DataQualityService) UpdateCheck(ctx context.Context, orgID, userID uuid.UUID, key types.CheckKey, req types.CheckSettingUpdateRequest) (*types.CheckSetting, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	check, ok := types.LookupCheck(key)
+	if !ok {
+		return nil, fmt.Errorf("invalid check: %s", key)
+	}
+	if req.ThresholdDays != nil {
+		if !check.HasThreshold() {
+			return nil, fmt.Errorf("check %s takes no threshold", key)
+		}
+		if *req.ThresholdDays < 1 {
+			return nil, fmt.Errorf("threshold for %s must be at least 1 day", key)
+		}
+	}
+
+	configs, err := s.resolveSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	var setting types.CheckSetting
+	for _, config := range configs {
+		if config.Key == key {
+			setting = config.Setting
+		}
+	}
+
+	if req.Enabled != nil {
+		setting.Enabled = *req.Enabled
+	}
+	if req.ThresholdDays != nil {
+		setting.ThresholdDays = req.ThresholdDays
+	}
+	setting.UpdatedBy = &userID
+
+	return s.repo.UpsertSetting(ctx, setting)
+}
+
+// Evaluate runs every check for one organization
+func (s *DataQualityService) Evaluate(ctx context.Context, orgID uuid.UUID) ([]types.CheckRun, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.evaluate(ctx, orgID)
+}
+
+// EvaluateAll runs every check for every organization. It is invoked by the
+// scheduled job and therefore bypasses the per-request permission check.
+func (s *DataQualityService) EvaluateAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.evaluate(ctx, orgID); err != nil {
+			failed++
+			s.logger.Error("Data quality evaluation failed", "organization_id", orgID, "error", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("data quality evaluation failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *DataQualityService) evaluate(ctx context.Context, orgID uuid.UUID) ([]types.CheckRun, error) {
+	configs, err := s.resolveSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	runs := make([]types.CheckRun, 0, len(configs))
+	var runErr error
+
+	for _, config := range configs {
+		run := types.CheckRun{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			CheckKey:       config.Key,
+			Module:         config.Module,
+			StartedAt:      s.now(),
+		}
+
+		if !config.Setting.Enabled {
+			run.Status = types.RunStatusSkipped
+		} else if eval, err := s.repo.Evaluate(ctx, orgID, config.Check, config.Setting.Cutoff(now), now); err != nil {
+			msg := err.Error()
+			run.Status = types.RunStatusFailed
+			run.Error = &msg
+			runErr = err
+		} else {
+			run.Status = types.RunStatusCompleted
+			run.CheckedCount = eval.Checked
+			run.OffendingCount = eval.Offending
+			run.DismissedCount = eval.Dismissed
+		}
+		run.FinishedAt = s.now()
+
+		if err := s.repo.CreateRun(ctx, run); err != nil {
+			s.logger.Error("Failed to record data quality run", "organization_id", orgID, "check", run.CheckKey, "error", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, runErr
+}
+
+// GetHygiene scores each module's data from the latest evaluation of its
+// enabled checks. Dismissed records count as passing.
+func (s *DataQualityService) GetHygiene(ctx context.Context, orgID uuid.UUID) (*types.HygieneReport, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	configs, err := s.resolveSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.repo.LatestRuns(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildHygieneReport(orgID, configs, runs, s.nextRunAt()), nil
+}
+
+// BuildHygieneReport scores modules in order of their first check. A module
+// or organization with nothing checked scores 100.
+func BuildHygieneReport(orgID uuid.UUID, configs []types.CheckConfig, runs []types.CheckRun, nextRunAt time.Time) *types.HygieneReport {
+	latest := make(map[types.CheckKey]types.CheckRun, len(runs))
+	for _, run := range runs {
+		latest[run.CheckKey] = run
+	}
+
+	report := &types.HygieneReport{OrganizationID: orgID, NextRunAt: nextRunAt, Modules: []types.ModuleHygiene{}}
+	modules := make(map[string]int)
+	var checked, offending int
+	for _, config := range configs {
+		i, ok := modules[config.Module]
+		if !ok {
+			i = len(report.Modules)
+			modules[config.Module] = i
+			report.Modules = append(report.Modules, types.ModuleHygiene{Module: config.Module, Checks: []types.CheckHygiene{}})
+		}
+		module := &report.Modules[i]
+
+		hygiene := types.CheckHygiene{CheckKey: config.Key, Name: config.Name, Enabled: config.Setting.Enabled}
+		if run, ok := latest[config.Key]; ok {
+			hygiene.CheckedCount = run.CheckedCount
+			hygiene.OffendingCount = run.OffendingCount
+			hygiene.DismissedCount = run.DismissedCount
+			finished := run.FinishedAt
+			hygiene.EvaluatedAt = &finished
+		}
+		module.Checks = append(module.Checks, hygiene)
+
+		if hygiene.Enabled {
+			module.CheckedCount += hygiene.CheckedCount
+			module.OffendingCount += hygiene.OffendingCount
+			checked += hygiene.CheckedCount
+			offending += hygiene.OffendingCount
+		}
+	}
+
+	for i := range report.Modules {
+		report.Modules[i].Score = hygieneScore(report.Modules[i].CheckedCount, report.Modules[i].OffendingCount)
+	}
+	report.Score = hygieneScore(checked, offending)
+	return report
+}
+
+// hygieneScore is the percentage of checked records passing, to one decimal
+func hygieneScore(checked, offending int) float64 {
+	if checked == 0 {
+		return 100
+	}
+	passing := math.Max(float64(checked-offending), 0)
+	return math.Round(passing/float64(checked)*1000) / 10
+}
+
+// ListIssues returns a page of the work queue, each issue with its quick fixes
+func (s *DataQualityService) ListIssues(ctx context.Context, orgID uuid.UUID, filter types.IssueFilter) ([]types.Issue, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.CheckKey != "" {
+		if _, ok := types.LookupCheck(filter.CheckKey); !ok {
+			return nil, fmt.Errorf("invalid check: %s", filter.CheckKey)
+		}
+	}
+	if filter.Status == "" {
+		filter.Status = types.IssueStatusOpen
+	}
+	if !filter.Status.IsValid() {
+		return nil, fmt.Errorf("invalid issue status: %s", filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultIssueLimit
+	}
+	if filter.Limit > MaxIssueLimit {
+		filter.Limit = MaxIssueLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.OrganizationID = orgID
+
+	issues, err := s.repo.ListIssues(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range issues {
+		issues[i].Actions = issueActions(issues[i])
+	}
+	return issues, nil
+}
+
+// DismissIssue takes an issue off the work queue; the record is no longer
+// flagged by the check
+func (s *DataQualityService) DismissIssue(ctx context.Context, orgID, userID, issueID uuid.UUID) (*types.Issue, error) {
+	if err := s.authService.CheckPermission(ctx, "data_quality:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DismissIssue(ctx, orgID, issueID, userID)
+}
+
+// issueActions lists the quick fixes of an open issue: the check's fixes on
+// the record, then dismissing the issue
+func issueActions(issue types.Issue) []types.QuickFixAction {
+	if issue.Status != types.IssueStatusOpen {
+		return nil
+	}
+	check, _ := types.LookupCheck(issue.CheckKey)
+	actions := make([]types.QuickFixAction, 0, len(check.Actions)+1)
+	for _, action := range check.Actions {
+		actions = append(actions, action.ForRecord(issue.RecordID))
+	}
+	return append(actions, types.QuickFixAction{
+		Key:    "dismiss",
+		Label:  "Dismiss",
+		Method: "POST",
+		Path:   fmt.Sprintf("/api/v1/data-quality/issues/%s/dismiss", issue.ID),
+	})
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/dataquality/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDataQualityRepo struct {
+	settings    []types.CheckSetting
+	evaluations map[types.CheckKey]types.Evaluation
+	evalErr     map[types.CheckKey]error
+	latest      []types.CheckRun
+	issues      []types.Issue
+
+	cutoffs  map[types.CheckKey]time.Time
+	runs     []types.CheckRun
+	upserted []types.CheckSetting
+	filters  []types.IssueFilter
+}
+
+func newFakeDataQualityRepo() *fakeDataQualityRepo {
+	return &fakeDataQualityRepo{
+		evaluations: make(map[types.CheckKey]types.Evaluation),
+		evalErr:     make(map[types.CheckKey]error),
+		cutoffs:     make(map[types.CheckKey]time.Time),
+	}
+}
+
+func (f *fakeDataQualityRepo) ListSettings(ctx context.Context, orgID uuid.UUID) ([]types.CheckSetting, error) {
+	return f.settings, nil
+}
+
+func (f *fakeDataQualityRepo) UpsertSetting(ctx context.Context, setting types.CheckSetting) (*types.CheckSetting, error) {
+	f.upserted = append(f.upserted, setting)
+	return &setting, nil
+}
+
+func (f *fakeDataQualityRepo) Evaluate(ctx context.Context, orgID uuid.UUID, check types.Check, cutoff, now time.Time) (types.Evaluation, error) {
+	f.cutoffs[check.Key] = cutoff
+	return f.evaluations[check.Key], f.evalErr[check.Key]
+}
+
+func (f *fakeDataQualityRepo) CreateRun(ctx context.Context, run types.CheckRun) error {
+	f.runs = append(f.runs, run)
+	return nil
+}
+
+func (f *fakeDataQualityRepo) LatestRuns(ctx context.Context, orgID uuid.UUID) ([]types.CheckRun, error) {
+	return f.latest, nil
+}
+
+func (f *fakeDataQualityRepo) ListIssues(ctx context.Context, filter types.IssueFilter) ([]types.Issue, error) {
+	f.filters = append(f.filters, filter)
+	return f.issues, nil
+}
+
+func (f *fakeDataQualityRepo) DismissIssue(ctx context.Context, orgID, issueID, dismissedBy uuid.UUID) (*types.Issue, error) {
+	return nil, errors.New("data quality issue not found or not open")
+}
+
+func (f *fakeDataQualityRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return []uuid.UUID{uuid.New()}, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func TestResolveSettingsFillsDefaultsAndThresholds(t *testing.T) {
+	orgID := uuid.New()
+	configs := ResolveSettings(orgID, []types.CheckSetting{
+		{OrganizationID: orgID, CheckKey: types.CheckStalledShipments, Enabled: false},
+	})
+
+	require.Len(t, configs, len(types.Checks))
+	assert.Equal(t, types.CheckLeadsWithoutOwner, configs[0].Key)
+	assert.True(t, configs[0].Setting.IsDefault)
+	assert.Equal(t, 1, *configs[0].Setting.ThresholdDays)
+	assert.Nil(t, configs[1].Setting.ThresholdDays)
+
+	// An override without a threshold keeps the default one
+	assert.False(t, configs[2].Setting.Enabled)
+	assert.False(t, configs[2].Setting.IsDefault)
+	assert.Equal(t, 3, *configs[2].Setting.ThresholdDays)
+}
+
+func TestUpdateCheckValidatesThreshold(t *testing.T) {
+	repo := newFakeDataQualityRepo()
+	svc := NewDataQualityService(repo, allowAll{}, nil)
+	orgID, userID := uuid.New(), uuid.New()
+
+	zero, five := 0, 5
+	_, err := svc.UpdateCheck(context.Background(), orgID, userID, types.CheckNegativeStock, types.CheckSettingUpdateRequest{ThresholdDays: &five})
+	assert.ErrorContains(t, err, "takes no threshold")
+	_, err = svc.UpdateCheck(context.Background(), orgID, userID, types.CheckStalledShipments, types.CheckSettingUpdateRequest{ThresholdDays: &zero})
+	assert.ErrorContains(t, err, "at least 1 day")
+	_, err = svc.UpdateCheck(context.Background(), orgID, userID, "unknown", types.CheckSettingUpdateRequest{})
+	assert.ErrorContains(t, err, "invalid check")
+
+	disabled := false
+	setting, err := svc.UpdateCheck(context.Background(), orgID, userID, types.CheckLeadsWithoutOwner, types.CheckSettingUpdateRequest{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, setting.Enabled)
+	assert.Equal(t, 1, *setting.ThresholdDays)
+	assert.Equal(t, &userID, setting.UpdatedBy)
+	assert.Len(t, repo.upserted, 1)
+}
+
+func TestEvaluateRecordsRunPerCheck(t *testing.T) {
+	now := time.Date(2025, 4, 2, 2, 0, 0, 0, time.UTC)
+	repo := newFakeDataQualityRepo()
+	repo.settings = []types.CheckSetting{{CheckKey: types.CheckContactsWithoutEmail, Enabled: false}}
+	repo.evaluations[types.CheckLeadsWithoutOwner] = types.Evaluation{Checked: 40, Offending: 4, Dismissed: 1}
+	repo.evalErr[types.CheckNegativeStock] = errors.New("boom")
+
+	svc := NewDataQualityService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return now }
+
+	runs, err := svc.Evaluate(context.Background(), uuid.New())
+	assert.EqualError(t, err, "boom")
+	require.Len(t, runs, 4)
+	assert.Equal(t, repo.runs, runs)
+
+	assert.Equal(t, types.RunStatusCompleted, runs[0].Status)
+	assert.Equal(t, "crm", runs[0].Module)
+	assert.Equal(t, 40, runs[0].CheckedCount)
+	assert.Equal(t, 4, runs[0].OffendingCount)
+	assert.Equal(t, 1, runs[0].DismissedCount)
+	assert.Equal(t, types.RunStatusSkipped, runs[1].Status)
+	assert.Equal(t, types.RunStatusCompleted, runs[2].Status)
+	assert.Equal(t, types.RunStatusFailed, runs[3].Status)
+	require.NotNil(t, runs[3].Error)
+
+	// Thresholds move the cutoff back; checks without one flag at once
+	assert.Equal(t, now.AddDate(0, 0, -1), repo.cutoffs[types.CheckLeadsWithoutOwner])
+	assert.Equal(t, now.AddDate(0, 0, -3), repo.cutoffs[types.CheckStalledShipments])
+	assert.Equal(t, now, repo.cutoffs[types.CheckNegativeStock])
+	assert.NotContains(t, repo.cutoffs, types.CheckContactsWithoutEmail)
+}
+
+func TestBuildHygieneReportScoresEnabledChecksPerModule(t *testing.T) {
+	orgID := uuid.New()
+	finished := time.Date(2025, 4, 2, 2, 5, 0, 0, time.UTC)
+	configs := ResolveSettings(orgID, []types.CheckSetting{{CheckKey: types.CheckNegativeStock, Enabled: false}})
+	runs := []types.CheckRun{
+		{CheckKey: types.CheckLeadsWithoutOwner, CheckedCount: 40, OffendingCount: 4, FinishedAt: finished},
+		{CheckKey: types.CheckContactsWithoutEmail, CheckedCount: 160, OffendingCount: 16, DismissedCount: 3, FinishedAt: finished},
+		{CheckKey: types.CheckStalledShipments, CheckedCount: 3, OffendingCount: 1, FinishedAt: finished},
+		{CheckKey: types.CheckNegativeStock, CheckedCount: 10, OffendingCount: 10, FinishedAt: finished},
+	}
+
+	report := BuildHygieneReport(orgID, configs, runs, finished.Add(RunInterval))
+	require.Len(t, report.Modules, 3)
+
+	crm := report.Modules[0]
+	assert.Equal(t, "crm", crm.Module)
+	assert.Equal(t, 200, crm.CheckedCount)
+	assert.Equal(t, 20, crm.OffendingCount)
+	assert.Equal(t, 90.0, crm.Score)
+	require.Len(t, crm.Checks, 2)
+	assert.Equal(t, 3, crm.Checks[1].DismissedCount)
+	assert.Equal(t, &finished, crm.Checks[1].EvaluatedAt)
+
+	assert.Equal(t, 66.7, report.Modules[1].Score)
+
+	// A disabled check is listed but not scored
+	inventory := report.Modules[2]
+	assert.Equal(t, 100.0, inventory.Score)
+	assert.False(t, inventory.Checks[0].Enabled)
+	assert.Equal(t, 10, inventory.Checks[0].OffendingCount)
+
+	assert.Equal(t, 89.7, report.Score)
+}
+
+func TestListIssuesAddsQuickFixes(t *testing.T) {
+	repo := newFakeDataQualityRepo()
+	issueID, leadID := uuid.New(), uuid.New()
+	repo.issues = []types.Issue{
+		{ID: issueID, CheckKey: types.CheckLeadsWithoutOwner, RecordID: leadID, Status: types.IssueStatusOpen},
+	}
+	svc := NewDataQualityService(repo, allowAll{}, nil)
+	orgID := uuid.New()
+
+	issues, err := svc.ListIssues(context.Background(), orgID, types.IssueFilter{Limit: 1000, Offset: -5})
+	require.NoError(t, err)
+	assert.Equal(t, types.IssueFilter{OrganizationID: orgID, Status: types.IssueStatusOpen, Limit: MaxIssueLimit}, repo.filters[0])
+	assert.Equal(t, []types.QuickFixAction{
+		{Key: "assign", Label: "Assign owner", Method: "PUT", Path: "/api/v1/leads/" + leadID.String(), Fields: []string{"assigned_to"}},
+		{Key: "dismiss", Label: "Dismiss", Method: "POST", Path: "/api/v1/data-quality/issues/" + issueID.String() + "/dismiss"},
+	}, issues[0].Actions)
+
+	_, err = svc.ListIssues(context.Background(), orgID, types.IssueFilter{Status: "closed"})
+	assert.ErrorContains(t, err, "invalid issue status")
+	_, err = svc.ListIssues(context.Background(), orgID, types.IssueFilter{CheckKey: "unknown"})
+	assert.ErrorContains(t, err, "invalid check")
+}
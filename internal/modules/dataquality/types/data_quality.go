@@ -0,0 +1,255 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CheckKey identifies a data quality check
+type CheckKey string
+
+const (
+	CheckLeadsWithoutOwner    CheckKey = "leads_without_owner"
+	CheckContactsWithoutEmail CheckKey = "contacts_without_email"
+	CheckStalledShipments     CheckKey = "stalled_shipments"
+	CheckNegativeStock        CheckKey = "negative_stock"
+)
+
+// QuickFixAction is a request that fixes an offending record. Path holds a
+// {record_id} placeholder; Fields lists the body fields the request expects.
+type QuickFixAction struct {
+	Key    string   `json:"key"`
+	Label  string   `json:"label"`
+	Method string   `json:"method"`
+	Path   string   `json:"path"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ForRecord returns the action with its path pointing at the record
+func (a QuickFixAction) ForRecord(recordID uuid.UUID) QuickFixAction {
+	a.Path = strings.ReplaceAll(a.Path, "{record_id}", recordID.String())
+	return a
+}
+
+// Check describes a data quality check. Checks with a default threshold
+// only flag records that have been offending for that many days.
+type Check struct {
+	Key                  CheckKey         `json:"key"`
+	Module               string           `json:"module"`
+	RecordType           string           `json:"record_type"`
+	Name                 string           `json:"name"`
+	Description          string           `json:"description"`
+	DefaultThresholdDays *int             `json:"default_threshold_days,omitempty"`
+	Actions              []QuickFixAction `json:"actions"`
+}
+
+// HasThreshold reports whether the check takes a threshold in days
+func (c Check) HasThreshold() bool {
+	return c.DefaultThresholdDays != nil
+}
+
+func days(n int) *int {
+	return &n
+}
+
+// Checks lists every data quality check, in evaluation order
+var Checks = []Check{
+	{
+		Key:                  CheckLeadsWithoutOwner,
+		Module:               "crm",
+		RecordType:           "lead",
+		Name:                 "Leads without owner",
+		Description:          "Open leads nobody has been assigned to for the threshold in days",
+		DefaultThresholdDays: days(1),
+		Actions: []QuickFixAction{
+			{Key: "assign", Label: "Assign owner", Method: "PUT", Path: "/api/v1/leads/{record_id}", Fields: []string{"assigned_to"}},
+		},
+	},
+	{
+		Key:         CheckContactsWithoutEmail,
+		Module:      "crm",
+		RecordType:  "contact",
+		Name:        "Contacts without email",
+		Description: "Contacts that cannot be emailed because they have no email address",
+		Actions: []QuickFixAction{
+			{Key: "add_email", Label: "Add email", Method: "PUT", Path: "/api/v2/contacts/{record_id}", Fields: []string{"email"}},
+		},
+	},
+	{
+		Key:                  CheckStalledShipments,
+		Module:               "delivery",
+		RecordType:           "shipment",
+		Name:                 "Stalled shipments",
+		Description:          "Scheduled or in-transit shipments without any tracking news for the threshold in days",
+		DefaultThresholdDays: days(3),
+		Actions: []QuickFixAction{
+			{Key: "update_status", Label: "Update status", Method: "PUT", Path: "/api/delivery/shipments/{record_id}/status", Fields: []string{"status"}},
+		},
+	},
+	{
+		Key:         CheckNegativeStock,
+		Module:      "inventory",
+		RecordType:  "stock_quant",
+		Name:        "Negative stock",
+		Description: "Stock on hand below zero in an internal location",
+		Actions: []QuickFixAction{
+			{Key: "adjust", Label: "Adjust stock", Method: "POST", Path: "/api/inventory/adjustments", Fields: []string{"product_id", "location_id", "quantity", "reason_code"}},
+		},
+	},
+}
+
+// LookupCheck returns the check with the key
+func LookupCheck(key CheckKey) (Check, bool) {
+	for _, check := range Checks {
+		if check.Key == key {
+			return check, true
+		}
+	}
+	return Check{}, false
+}
+
+// CheckSetting is the configuration of one check in an organization
+type CheckSetting struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	CheckKey       CheckKey   `json:"check_key" db:"check_key"`
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	ThresholdDays  *int       `json:"threshold_days,omitempty" db:"threshold_days"`
+	IsDefault      bool       `json:"is_default" db:"-"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	UpdatedBy      *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Cutoff returns the instant before which records must have started
+// offending to be flagged. Checks without a threshold flag records at once.
+func (s CheckSetting) Cutoff(now time.Time) time.Time {
+	if s.ThresholdDays == nil {
+		return now
+	}
+	return now.AddDate(0, 0, -*s.ThresholdDays)
+}
+
+// CheckSettingUpdateRequest configures a check
+type CheckSettingUpdateRequest struct {
+	Enabled       *bool `json:"enabled,omitempty"`
+	ThresholdDays *int  `json:"threshold_days,omitempty"`
+}
+
+// CheckConfig is a check together with its configuration in an organization
+type CheckConfig struct {
+	Check
+	Setting CheckSetting `json:"setting"`
+}
+
+// RunStatus represents the outcome of a check evaluation
+type RunStatus string
+
+const (
+	RunStatusCompleted RunStatus = "completed"
+	RunStatusSkipped   RunStatus = "skipped"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+// CheckRun records one evaluation of a check
+type CheckRun struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	CheckKey       CheckKey  `json:"check_key" db:"check_key"`
+	Module         string    `json:"module" db:"module"`
+	// CheckedCount is how many records the check looked at
+	CheckedCount int `json:"checked_count" db:"checked_count"`
+	// OffendingCount is how many of them fail the check, dismissed ones aside
+	OffendingCount int       `json:"offending_count" db:"offending_count"`
+	DismissedCount int       `json:"dismissed_count" db:"dismissed_count"`
+	Status         RunStatus `json:"status" db:"status"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	StartedAt      time.Time `json:"started_at" db:"started_at"`
+	FinishedAt     time.Time `json:"finished_at" db:"finished_at"`
+}
+
+// Evaluation is what a check found in an organization
+type Evaluation struct {
+	Checked   int
+	Offending int
+	Dismissed int
+}
+
+// IssueStatus represents the state of an offending record in the work queue
+type IssueStatus string
+
+const (
+	IssueStatusOpen      IssueStatus = "open"
+	IssueStatusResolved  IssueStatus = "resolved"
+	IssueStatusDismissed IssueStatus = "dismissed"
+)
+
+// IsValid reports whether the issue status is known
+func (s IssueStatus) IsValid() bool {
+	switch s {
+	case IssueStatusOpen, IssueStatusResolved, IssueStatusDismissed:
+		return true
+	}
+	return false
+}
+
+// Issue is a record failing a check
+type Issue struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id" db:"organization_id"`
+	CheckKey       CheckKey          `json:"check_key" db:"check_key"`
+	Module         string            `json:"module" db:"module"`
+	RecordType     string            `json:"record_type" db:"record_type"`
+	RecordID       uuid.UUID         `json:"record_id" db:"record_id"`
+	RecordLabel    string            `json:"record_label" db:"record_label"`
+	Details        map[string]string `json:"details,omitempty" db:"details"`
+	Status         IssueStatus       `json:"status" db:"status"`
+	FirstSeenAt    time.Time         `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt     time.Time         `json:"last_seen_at" db:"last_seen_at"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty" db:"resolved_at"`
+	DismissedAt    *time.Time        `json:"dismissed_at,omitempty" db:"dismissed_at"`
+	DismissedBy    *uuid.UUID        `json:"dismissed_by,omitempty" db:"dismissed_by"`
+	// Actions are the quick fixes for the record, resolved for the work queue
+	Actions []QuickFixAction `json:"actions,omitempty" db:"-"`
+}
+
+// IssueFilter represents filtering criteria for the work queue
+type IssueFilter struct {
+	OrganizationID uuid.UUID
+	Module         string
+	CheckKey       CheckKey
+	Status         IssueStatus
+	Limit          int
+	Offset         int
+}
+
+// CheckHygiene is the latest evaluation of a check
+type CheckHygiene struct {
+	CheckKey       CheckKey   `json:"check_key"`
+	Name           string     `json:"name"`
+	Enabled        bool       `json:"enabled"`
+	CheckedCount   int        `json:"checked_count"`
+	OffendingCount int        `json:"offending_count"`
+	DismissedCount int        `json:"dismissed_count"`
+	EvaluatedAt    *time.Time `json:"evaluated_at,omitempty"`
+}
+
+// ModuleHygiene scores the data of a module from 0 to 100: the share of the
+// records its enabled checks looked at that pass them
+type ModuleHygiene struct {
+	Module         string         `json:"module"`
+	Score          float64        `json:"score"`
+	CheckedCount   int            `json:"checked_count"`
+	OffendingCount int            `json:"offending_count"`
+	Checks         []CheckHygiene `json:"checks"`
+}
+
+// HygieneReport is the hygiene dashboard of an organization
+type HygieneReport struct {
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	Score          float64         `json:"score"`
+	NextRunAt      time.Time       `json:"next_run_at"`
+	Modules        []ModuleHygiene `json:"modules"`
+}
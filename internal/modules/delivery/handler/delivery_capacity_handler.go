@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeliveryCapacityHandler struct {
+	service *deliveryservice.DeliveryCapacityService
+}
+
+func NewDeliveryCapacityHandler(service *deliveryservice.DeliveryCapacityService) *DeliveryCapacityHandler {
+	return &DeliveryCapacityHandler{
+		service: service,
+	}
+}
+
+func (h *DeliveryCapacityHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/delivery/routes/:id/capacity", h.GetRouteCapacity)
+	router.GET("/api/v1/delivery/vehicles/:id/compartments", h.GetVehicleCompartments)
+	router.PUT("/api/v1/delivery/vehicles/:id/compartments", h.SetVehicleCompartments)
+}
+
+// GetRouteCapacity handles GET /api/v1/delivery/routes/:id/capacity
+func (h *DeliveryCapacityHandler) GetRouteCapacity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetRouteCapacity(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetVehicleCompartments handles GET /api/v1/delivery/vehicles/:id/compartments
+func (h *DeliveryCapacityHandler) GetVehicleCompartments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid vehicle ID", http.StatusBadRequest)
+		return
+	}
+
+	capacity, err := h.service.GetVehicleCapacity(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if capacity == nil {
+		http.Error(w, "Delivery vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(capacity)
+}
+
+// SetVehicleCompartments handles PUT /api/v1/delivery/vehicles/:id/compartments.
+// The body is the full list of compartments; it replaces the current one.
+func (h *DeliveryCapacityHandler) SetVehicleCompartments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid vehicle ID", http.StatusBadRequest)
+		return
+	}
+
+	var req []deliverytypes.VehicleCompartment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	compartments, err := h.service.SetVehicleCompartments(r.Context(), authCtx.OrganizationID, id, req)
+	if errors.Is(err, deliveryservice.ErrInvalidCompartment) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if compartments == nil {
+		http.Error(w, "Delivery vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(compartments)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeliveryConsolidationHandler struct {
+	service *deliveryservice.DeliveryConsolidationService
+}
+
+func NewDeliveryConsolidationHandler(service *deliveryservice.DeliveryConsolidationService) *DeliveryConsolidationHandler {
+	return &DeliveryConsolidationHandler{
+		service: service,
+	}
+}
+
+func (h *DeliveryConsolidationHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/delivery/consolidation/suggestions", h.ListSuggestions)
+	router.GET("/api/v1/delivery/consolidation/settings", h.GetSettings)
+	router.PUT("/api/v1/delivery/consolidation/settings", h.UpdateSettings)
+	router.GET("/api/v1/delivery/consolidations", h.ListConsolidations)
+	router.POST("/api/v1/delivery/consolidations", h.ApplyConsolidation)
+}
+
+// ListSuggestions handles GET /api/v1/delivery/consolidation/suggestions
+func (h *DeliveryConsolidationHandler) ListSuggestions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.service.Suggest(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// GetSettings handles GET /api/v1/delivery/consolidation/settings
+func (h *DeliveryConsolidationHandler) GetSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSettings handles PUT /api/v1/delivery/consolidation/settings
+func (h *DeliveryConsolidationHandler) UpdateSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var settings deliverytypes.ConsolidationSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateSettings(r.Context(), authCtx.OrganizationID, settings)
+	if errors.Is(err, deliveryservice.ErrInvalidConsolidation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// ListConsolidations handles GET /api/v1/delivery/consolidations
+func (h *DeliveryConsolidationHandler) ListConsolidations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	consolidations, err := h.service.ListConsolidations(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(consolidations)
+}
+
+// ApplyConsolidation handles POST /api/v1/delivery/consolidations
+func (h *DeliveryConsolidationHandler) ApplyConsolidation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req deliverytypes.ConsolidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := authCtx.UserID
+	consolidation, err := h.service.Apply(r.Context(), authCtx.OrganizationID, &userID, req)
+	if errors.Is(err, deliveryservice.ErrInvalidConsolidation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, deliveryservice.ErrConsolidationConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(consolidation)
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultReportRange is the range of a sustainability report without ?from
+const defaultReportRange = 30 * 24 * time.Hour
+
+type DeliveryEmissionHandler struct {
+	service *deliveryservice.DeliveryEmissionService
+}
+
+func NewDeliveryEmissionHandler(service *deliveryservice.DeliveryEmissionService) *DeliveryEmissionHandler {
+	return &DeliveryEmissionHandler{
+		service: service,
+	}
+}
+
+func (h *DeliveryEmissionHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/delivery/routes/:id/emissions", h.GetRouteEmissions)
+	router.GET("/api/v1/delivery/sustainability/report", h.GetSustainabilityReport)
+	router.GET("/api/v1/delivery/sustainability/factors", h.GetFactors)
+	router.PUT("/api/v1/delivery/sustainability/factors", h.UpdateFactors)
+}
+
+// GetRouteEmissions handles GET /api/v1/delivery/routes/:id/emissions
+func (h *DeliveryEmissionHandler) GetRouteEmissions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	emission, err := h.service.GetRouteEmissions(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if emission == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(emission)
+}
+
+// GetSustainabilityReport handles GET /api/v1/delivery/sustainability/report.
+// ?from and ?to are RFC 3339 timestamps and default to the last 30 days;
+// ?period is day, week or month; ?customer_id limits the report to one customer.
+func (h *DeliveryEmissionHandler) GetSustainabilityReport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := deliverytypes.SustainabilityReportFilter{
+		OrganizationID: authCtx.OrganizationID,
+		To:             time.Now(),
+		Period:         deliverytypes.EmissionPeriod(query.Get("period")),
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid to timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.To = to
+	}
+	filter.From = filter.To.Add(-defaultReportRange)
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.From = from
+	}
+	if v := query.Get("customer_id"); v != "" {
+		customerID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+			return
+		}
+		filter.CustomerID = &customerID
+	}
+
+	report, err := h.service.GetSustainabilityReport(r.Context(), filter)
+	if errors.Is(err, deliveryservice.ErrInvalidEmissionReport) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetFactors handles GET /api/v1/delivery/sustainability/factors
+func (h *DeliveryEmissionHandler) GetFactors(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	factors, err := h.service.GetFactors(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(factors)
+}
+
+// UpdateFactors handles PUT /api/v1/delivery/sustainability/factors
+func (h *DeliveryEmissionHandler) UpdateFactors(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var factors deliverytypes.EmissionFactors
+	if err := json.NewDecoder(r.Body).Decode(&factors); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateFactors(r.Context(), authCtx.OrganizationID, factors)
+	if errors.Is(err, deliveryservice.ErrInvalidEmissionReport) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeliveryManifestHandler struct {
+	service *deliveryservice.DeliveryManifestService
+}
+
+func NewDeliveryManifestHandler(service *deliveryservice.DeliveryManifestService) *DeliveryManifestHandler {
+	return &DeliveryManifestHandler{
+		service: service,
+	}
+}
+
+func (h *DeliveryManifestHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/delivery/routes/:id/manifest", h.GetRouteManifest)
+}
+
+// GetRouteManifest handles GET /api/v1/delivery/routes/:id/manifest. It
+// returns JSON, or a PDF with ?format=pdf or an Accept of application/pdf.
+func (h *DeliveryManifestHandler) GetRouteManifest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	if wantsPDF(r) {
+		pdf, manifest, err := h.service.RenderRouteManifestPDF(r.Context(), authCtx.OrganizationID, id)
+		if err != nil {
+			if errors.Is(err, deliveryservice.ErrManifestPDFUnavailable) {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if manifest == nil {
+			http.Error(w, "Delivery route not found", http.StatusNotFound)
+			return
+		}
+
+		filename := "manifest-" + manifest.RouteID.String() + ".pdf"
+		if manifest.RouteCode != "" {
+			filename = "manifest-" + safeFilename(manifest.RouteCode) + ".pdf"
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdf)
+		return
+	}
+
+	manifest, err := h.service.GetRouteManifest(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if manifest == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(manifest)
+}
+
+func wantsPDF(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "pdf"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/pdf")
+}
+
+func safeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
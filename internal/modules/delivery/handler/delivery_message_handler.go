@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/realtime"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type DeliveryMessageHandler struct {
+	service *deliveryservice.DeliveryMessageService
+}
+
+func NewDeliveryMessageHandler(service *deliveryservice.DeliveryMessageService) *DeliveryMessageHandler {
+	return &DeliveryMessageHandler{
+		service: service,
+	}
+}
+
+func (h *DeliveryMessageHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/delivery/routes/:id/messages", h.ListMessages)
+	router.POST("/api/v1/delivery/routes/:id/messages", h.SendMessage)
+	router.POST("/api/v1/delivery/routes/:id/messages/read", h.MarkRead)
+	router.GET("/api/v1/delivery/routes/:id/messages/stream", h.StreamMessages)
+}
+
+// ListMessages handles GET /api/v1/delivery/routes/:id/messages. Messages
+// come newest first; ?before=<created_at of the oldest> loads older ones.
+func (h *DeliveryMessageHandler) ListMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	filter := deliverytypes.DeliveryMessageFilter{OrganizationID: authCtx.OrganizationID, RouteID: id}
+	query := r.URL.Query()
+	if v := query.Get("stop_id"); v != "" {
+		stopID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid stop ID", http.StatusBadRequest)
+			return
+		}
+		filter.StopID = &stopID
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			http.Error(w, "Invalid before timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.Before = &before
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	messages, err := h.service.ListMessages(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if messages == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(messages)
+}
+
+// SendMessage handles POST /api/v1/delivery/routes/:id/messages
+func (h *DeliveryMessageHandler) SendMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	var req deliverytypes.DeliveryMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.service.SendMessage(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if errors.Is(err, deliveryservice.ErrInvalidMessage) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if message == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// MarkRead handles POST /api/v1/delivery/routes/:id/messages/read. An empty
+// body marks every unread message of the route.
+func (h *DeliveryMessageHandler) MarkRead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	var req deliverytypes.MessageReadRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	receipt, err := h.service.MarkRead(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if receipt == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// StreamMessages handles GET /api/v1/delivery/routes/:id/messages/stream. It
+// streams "message" and "read" server-sent events until the client leaves.
+func (h *DeliveryMessageHandler) StreamMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.service.Subscribe(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sub == nil {
+		http.Error(w, "Delivery route not found", http.StatusNotFound)
+		return
+	}
+	defer sub.Close()
+
+	realtime.ServeSSE(w, r, sub, realtime.DefaultKeepAlive)
+}
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
 	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	trainingservice "github.com/KevTiv/alieze-erp/internal/modules/training/service"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -57,8 +59,13 @@ func (h *DeliveryTrackingHandler) CreateShipment(w http.ResponseWriter, r *http.
 		return
 	}
 
+	req.Metadata = withCapacityOverride(r, req.Metadata)
+
 	createdShipment, err := h.service.CreateShipment(r.Context(), req)
 	if err != nil {
+		if writeCapacityError(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -151,6 +158,10 @@ func (h *DeliveryTrackingHandler) UpdateShipmentStatus(w http.ResponseWriter, r
 	}
 
 	updatedShipment, err := h.service.UpdateShipmentStatus(r.Context(), id, deliverytypes.ShipmentStatus(req.Status))
+	if errors.Is(err, deliveryservice.ErrInvalidShipmentStatus) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -302,8 +313,17 @@ func (h *DeliveryTrackingHandler) CreateRouteAssignment(w http.ResponseWriter, r
 	// Set the route ID from the URL
 	req.RouteID = routeID
 
+	req.Metadata = withCapacityOverride(r, req.Metadata)
+
 	createdAssignment, err := h.service.CreateRouteAssignment(r.Context(), req)
 	if err != nil {
+		if writeCapacityError(w, err) {
+			return
+		}
+		if errors.Is(err, trainingservice.ErrNotQualified) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -347,8 +367,13 @@ func (h *DeliveryTrackingHandler) CreateRouteStop(w http.ResponseWriter, r *http
 	// Set the route ID from the URL
 	req.RouteID = routeID
 
+	req.Metadata = withCapacityOverride(r, req.Metadata)
+
 	createdStop, err := h.service.CreateRouteStop(r.Context(), req)
 	if err != nil {
+		if writeCapacityError(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -428,3 +453,33 @@ func (h *DeliveryTrackingHandler) UpdateRouteStopStatus(w http.ResponseWriter, r
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedStop)
 }
+
+// withCapacityOverride sets the capacity override flag when the request has
+// ?allow_overload=true, so an overloaded route is accepted deliberately
+func withCapacityOverride(r *http.Request, metadata map[string]interface{}) map[string]interface{} {
+	if r.URL.Query().Get("allow_overload") != "true" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata[deliverytypes.CapacityOverrideKey] = true
+	return metadata
+}
+
+// writeCapacityError answers capacity violations with 422 and the
+// violations, and reports whether err was one
+func writeCapacityError(w http.ResponseWriter, err error) bool {
+	var capacityErr *deliverytypes.CapacityError
+	if !errors.As(err, &capacityErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      capacityErr.Error(),
+		"violations": capacityErr.Violations,
+	})
+	return true
+}
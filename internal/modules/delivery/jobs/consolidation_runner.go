@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
+)
+
+// ConsolidationRunner merges same-address shipments in the background for
+// organizations that enabled automatic consolidation. Shipments are locked
+// while merged, so every instance can run one.
+type ConsolidationRunner struct {
+	consolidationService *deliveryservice.DeliveryConsolidationService
+	interval             time.Duration
+	logger               *slog.Logger
+}
+
+func NewConsolidationRunner(consolidationService *deliveryservice.DeliveryConsolidationService, interval time.Duration, logger *slog.Logger) *ConsolidationRunner {
+	return &ConsolidationRunner{
+		consolidationService: consolidationService,
+		interval:             interval,
+		logger:               logger,
+	}
+}
+
+// Start consolidates every interval until ctx is cancelled
+func (r *ConsolidationRunner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.run(ctx)
+			}
+		}
+	}()
+}
+
+func (r *ConsolidationRunner) run(ctx context.Context) {
+	applied, err := r.consolidationService.AutoConsolidateAll(ctx)
+	if err != nil {
+		r.logger.Error("Automatic shipment consolidation failed", "error", err)
+	}
+	if applied > 0 {
+		r.logger.Info("Consolidated shipments automatically", "consolidations", applied)
+	}
+}
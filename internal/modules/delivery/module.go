@@ -8,12 +8,18 @@ import (
 	"time"
 
 	deliveryhandler "github.com/KevTiv/alieze-erp/internal/modules/delivery/handler"
+	deliveryjobs "github.com/KevTiv/alieze-erp/internal/modules/delivery/jobs"
 	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
 	deliveryservice "github.com/KevTiv/alieze-erp/internal/modules/delivery/service"
 	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
 	inventorytypes "github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
 	salestypes "github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/realtime"
 	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -24,8 +30,17 @@ type DeliveryModule struct {
 	deliveryVehicleHandler  *deliveryhandler.DeliveryVehicleHandler
 	deliveryRouteHandler    *deliveryhandler.DeliveryRouteHandler
 	deliveryTrackingHandler *deliveryhandler.DeliveryTrackingHandler
+	deliveryManifestHandler *deliveryhandler.DeliveryManifestHandler
+	deliveryCapacityHandler *deliveryhandler.DeliveryCapacityHandler
+	deliveryMessageHandler  *deliveryhandler.DeliveryMessageHandler
+	consolidationHandler    *deliveryhandler.DeliveryConsolidationHandler
+	emissionHandler         *deliveryhandler.DeliveryEmissionHandler
+	consolidationRunner     *deliveryjobs.ConsolidationRunner
 	deliveryRouteService    *deliveryservice.DeliveryRouteService
+	deliveryManifestService *deliveryservice.DeliveryManifestService
+	deliveryProofService    *deliveryservice.DeliveryProofService
 	deliveryTrackingService *deliveryservice.DeliveryTrackingService
+	deliveryMessageService  *deliveryservice.DeliveryMessageService
 	inventoryService        InventoryServiceInterface
 	logger                  *slog.Logger
 }
@@ -46,6 +61,34 @@ func (m *DeliveryModule) Name() string {
 	return "delivery"
 }
 
+// GetManifestService returns the route manifest service, which also serves
+// as the route manifest data source for document templates
+func (m *DeliveryModule) GetManifestService() *deliveryservice.DeliveryManifestService {
+	return m.deliveryManifestService
+}
+
+// GetProofService returns the proof of delivery service, the data source of
+// proof of delivery document templates
+func (m *DeliveryModule) GetProofService() *deliveryservice.DeliveryProofService {
+	return m.deliveryProofService
+}
+
+// SetPushNotifier sends route messages as push notifications to drivers and
+// dispatchers. It must be called after Init.
+func (m *DeliveryModule) SetPushNotifier(notifier push.Notifier) {
+	if m.deliveryMessageService != nil {
+		m.deliveryMessageService.SetPushNotifier(notifier)
+	}
+}
+
+// SetDriverQualifier only lets drivers holding the required certifications
+// be assigned to routes carrying dangerous goods. It must be called after Init.
+func (m *DeliveryModule) SetDriverQualifier(qualifier deliveryservice.DriverQualifier) {
+	if m.deliveryTrackingService != nil {
+		m.deliveryTrackingService.SetDriverQualifier(m.deliveryManifestService, qualifier)
+	}
+}
+
 // Init initializes the Delivery Tracking module
 func (m *DeliveryModule) Init(ctx context.Context, deps registry.Dependencies) error {
 	// Initialize logger
@@ -56,13 +99,36 @@ func (m *DeliveryModule) Init(ctx context.Context, deps registry.Dependencies) e
 	deliveryVehicleRepo := deliveryrepository.NewDeliveryVehicleRepository(deps.DB)
 	deliveryRouteRepo := deliveryrepository.NewDeliveryRouteRepository(deps.DB)
 	deliveryTrackingRepo := deliveryrepository.NewDeliveryTrackingRepository(deps.DB)
+	deliveryManifestRepo := deliveryrepository.NewDeliveryManifestRepository(deps.DB)
+	deliveryProofRepo := deliveryrepository.NewDeliveryProofRepository(deps.DB)
+	deliveryCapacityRepo := deliveryrepository.NewDeliveryCapacityRepository(deps.DB)
+	deliveryMessageRepo := deliveryrepository.NewDeliveryMessageRepository(deps.DB)
+	consolidationRepo := deliveryrepository.NewDeliveryConsolidationRepository(deps.DB)
+	emissionRepo := deliveryrepository.NewDeliveryEmissionRepository(deps.DB)
 
 	// Create services with event bus support
 	deliveryVehicleService := deliveryservice.NewDeliveryVehicleService(deliveryVehicleRepo)
 	// We need to pass the event bus to services if they need to publish events
 	// Casting deps.EventBus to interface{} as the service expects
 	m.deliveryRouteService = deliveryservice.NewDeliveryRouteServiceWithEventBus(deliveryRouteRepo, deps.EventBus)
+	m.deliveryRouteService.SetBusinessCalendars(calendar.NewStore(deps.DB))
 	m.deliveryTrackingService = deliveryservice.NewDeliveryTrackingServiceWithEventBus(deliveryTrackingRepo, deps.EventBus)
+	m.deliveryManifestService = deliveryservice.NewDeliveryManifestService(deliveryManifestRepo)
+	m.deliveryProofService = deliveryservice.NewDeliveryProofService(deliveryProofRepo)
+	deliveryCapacityService := deliveryservice.NewDeliveryCapacityService(deliveryCapacityRepo)
+	// Adding stops, shipments or a vehicle to a route is checked against the vehicle's capacity
+	m.deliveryTrackingService.SetCapacityChecker(deliveryCapacityService)
+	// Route messages reach connected drivers and dispatchers through the realtime hub
+	m.deliveryMessageService = deliveryservice.NewDeliveryMessageService(deliveryMessageRepo, realtime.NewHub(realtime.DefaultBuffer), m.logger)
+	consolidationService := deliveryservice.NewDeliveryConsolidationService(consolidationRepo)
+	emissionService := deliveryservice.NewDeliveryEmissionService(emissionRepo)
+
+	// PDF manifests need wkhtmltopdf; JSON manifests work without it
+	if pdfGenerator, err := templates.NewPDFGenerator(templates.NewEngine("")); err != nil {
+		m.logger.Warn("PDF route manifests disabled", "error", err)
+	} else {
+		m.deliveryManifestService.SetPDFRenderer(pdfGenerator)
+	}
 
 	// Get inventory service from dependencies if available
 	if deps.InventoryService != nil {
@@ -80,6 +146,15 @@ func (m *DeliveryModule) Init(ctx context.Context, deps registry.Dependencies) e
 	m.deliveryVehicleHandler = deliveryhandler.NewDeliveryVehicleHandler(deliveryVehicleService)
 	m.deliveryRouteHandler = deliveryhandler.NewDeliveryRouteHandler(m.deliveryRouteService)
 	m.deliveryTrackingHandler = deliveryhandler.NewDeliveryTrackingHandler(m.deliveryTrackingService)
+	m.deliveryManifestHandler = deliveryhandler.NewDeliveryManifestHandler(m.deliveryManifestService)
+	m.deliveryCapacityHandler = deliveryhandler.NewDeliveryCapacityHandler(deliveryCapacityService)
+	m.deliveryMessageHandler = deliveryhandler.NewDeliveryMessageHandler(m.deliveryMessageService)
+	m.consolidationHandler = deliveryhandler.NewDeliveryConsolidationHandler(consolidationService)
+	m.emissionHandler = deliveryhandler.NewDeliveryEmissionHandler(emissionService)
+
+	// Merge same-address shipments in the background for organizations that enabled it
+	m.consolidationRunner = deliveryjobs.NewConsolidationRunner(consolidationService, deliveryservice.ConsolidationPollInterval, m.logger)
+	m.consolidationRunner.Start(ctx)
 
 	m.logger.Info("Delivery Tracking module initialized successfully")
 	return nil
@@ -99,10 +174,30 @@ func (m *DeliveryModule) RegisterRoutes(router interface{}) {
 			if m.deliveryTrackingHandler != nil {
 				m.deliveryTrackingHandler.RegisterRoutes(r)
 			}
+			if m.deliveryManifestHandler != nil {
+				m.deliveryManifestHandler.RegisterRoutes(r)
+			}
+			if m.deliveryCapacityHandler != nil {
+				m.deliveryCapacityHandler.RegisterRoutes(r)
+			}
+			if m.deliveryMessageHandler != nil {
+				m.deliveryMessageHandler.RegisterRoutes(r)
+			}
+			if m.consolidationHandler != nil {
+				m.consolidationHandler.RegisterRoutes(r)
+			}
+			if m.emissionHandler != nil {
+				m.emissionHandler.RegisterRoutes(r)
+			}
 		}
 	}
 }
 
+// Enums returns the enumerations the delivery module lists to clients
+func (m *DeliveryModule) Enums() []enums.Enum {
+	return []enums.Enum{deliverytypes.ShipmentStatusEnum, deliverytypes.ShipmentTypeEnum}
+}
+
 // RegisterEventHandlers registers event handlers for the Delivery Tracking module
 func (m *DeliveryModule) RegisterEventHandlers(bus interface{}) {
 	if bus == nil {
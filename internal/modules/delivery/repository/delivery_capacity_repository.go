@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryCapacityRepository reads vehicle limits and shipment loads for capacity checks
+type DeliveryCapacityRepository interface {
+	FindVehicleCapacity(ctx context.Context, orgID, vehicleID uuid.UUID) (*deliverytypes.VehicleCapacity, error)
+	FindRouteVehicleCapacity(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.VehicleCapacity, error)
+	ReplaceCompartments(ctx context.Context, orgID, vehicleID uuid.UUID, compartments []deliverytypes.VehicleCompartment) ([]deliverytypes.VehicleCompartment, error)
+	FindRouteLoads(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ShipmentLoad, error)
+	FindShipmentLoads(ctx context.Context, orgID, shipmentID uuid.UUID) ([]deliverytypes.ShipmentLoad, error)
+	FindPickingLoads(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ShipmentLoad, error)
+}
+
+type deliveryCapacityRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryCapacityRepository(db *sql.DB) DeliveryCapacityRepository {
+	return &deliveryCapacityRepository{db: db}
+}
+
+// FindVehicleCapacity returns the vehicle's limits and compartments, or nil
+// when the organization has no such vehicle
+func (r *deliveryCapacityRepository) FindVehicleCapacity(ctx context.Context, orgID, vehicleID uuid.UUID) (*deliverytypes.VehicleCapacity, error) {
+	query := `
+		SELECT id, name, max_weight, max_volume
+		FROM delivery_vehicles
+		WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+	`
+	return r.findCapacity(ctx, orgID, query, orgID, vehicleID)
+}
+
+// FindRouteVehicleCapacity returns the capacity of the vehicle of the
+// route's latest open assignment, or nil when no vehicle is assigned
+func (r *deliveryCapacityRepository) FindRouteVehicleCapacity(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.VehicleCapacity, error) {
+	query := `
+		SELECT v.id, v.name, v.max_weight, v.max_volume
+		FROM delivery_route_assignments a
+		JOIN delivery_vehicles v ON v.id = a.vehicle_id AND v.deleted_at IS NULL
+		WHERE a.organization_id = $1 AND a.route_id = $2
+			AND a.assignment_status IN ('assigned', 'accepted', 'completed')
+		ORDER BY a.assigned_at DESC
+		LIMIT 1
+	`
+	return r.findCapacity(ctx, orgID, query, orgID, routeID)
+}
+
+func (r *deliveryCapacityRepository) findCapacity(ctx context.Context, orgID uuid.UUID, query string, args ...interface{}) (*deliverytypes.VehicleCapacity, error) {
+	var capacity deliverytypes.VehicleCapacity
+	var maxWeight, maxVolume sql.NullFloat64
+
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&capacity.VehicleID,
+		&capacity.VehicleName,
+		&maxWeight,
+		&maxVolume,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find vehicle capacity: %w", err)
+	}
+
+	if maxWeight.Valid {
+		capacity.MaxWeight = &maxWeight.Float64
+	}
+	if maxVolume.Valid {
+		capacity.MaxVolume = &maxVolume.Float64
+	}
+
+	capacity.Compartments, err = r.findCompartments(ctx, orgID, capacity.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &capacity, nil
+}
+
+func (r *deliveryCapacityRepository) findCompartments(ctx context.Context, orgID, vehicleID uuid.UUID) ([]deliverytypes.VehicleCompartment, error) {
+	query := `
+		SELECT id, organization_id, vehicle_id, name, temperature_class, max_weight, max_volume
+		FROM delivery_vehicle_compartments
+		WHERE organization_id = $1 AND vehicle_id = $2
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vehicle compartments: %w", err)
+	}
+	defer rows.Close()
+
+	compartments := []deliverytypes.VehicleCompartment{}
+	for rows.Next() {
+		var compartment deliverytypes.VehicleCompartment
+		var maxWeight, maxVolume sql.NullFloat64
+
+		if err := rows.Scan(
+			&compartment.ID,
+			&compartment.OrganizationID,
+			&compartment.VehicleID,
+			&compartment.Name,
+			&compartment.TemperatureClass,
+			&maxWeight,
+			&maxVolume,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle compartment: %w", err)
+		}
+
+		if maxWeight.Valid {
+			compartment.MaxWeight = &maxWeight.Float64
+		}
+		if maxVolume.Valid {
+			compartment.MaxVolume = &maxVolume.Float64
+		}
+
+		compartments = append(compartments, compartment)
+	}
+
+	return compartments, rows.Err()
+}
+
+// ReplaceCompartments sets the vehicle's compartments
+func (r *deliveryCapacityRepository) ReplaceCompartments(ctx context.Context, orgID, vehicleID uuid.UUID, compartments []deliverytypes.VehicleCompartment) ([]deliverytypes.VehicleCompartment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM delivery_vehicle_compartments WHERE organization_id = $1 AND vehicle_id = $2`,
+		orgID, vehicleID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to clear vehicle compartments: %w", err)
+	}
+
+	for _, compartment := range compartments {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO delivery_vehicle_compartments (id, organization_id, vehicle_id, name, temperature_class, max_weight, max_volume)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`,
+			compartment.ID, orgID, vehicleID, compartment.Name, compartment.TemperatureClass, compartment.MaxWeight, compartment.MaxVolume,
+		); err != nil {
+			return nil, fmt.Errorf("failed to create vehicle compartment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit vehicle compartments: %w", err)
+	}
+
+	return r.findCompartments(ctx, orgID, vehicleID)
+}
+
+// loadColumns aggregates stock move lines per temperature class. Products
+// without a temperature class travel ambient.
+const loadColumns = `
+	COALESCE(pr.temperature_class, 'ambient') AS temperature_class,
+	COALESCE(SUM(pr.weight * m.product_uom_qty), 0),
+	COALESCE(SUM(pr.volume * m.product_uom_qty), 0),
+	COUNT(*) FILTER (WHERE pr.weight IS NULL OR pr.volume IS NULL)
+`
+
+// FindRouteLoads returns the loads of the shipments still to be delivered on
+// the route, whether linked through a stop or the shipment's route
+func (r *deliveryCapacityRepository) FindRouteLoads(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	query := `
+		WITH route_shipments AS (
+			SELECT sh.id, sh.picking_id
+			FROM delivery_shipments sh
+			WHERE sh.organization_id = $1 AND sh.deleted_at IS NULL
+				AND sh.status NOT IN ('delivered', 'failed', 'cancelled')
+				AND (
+					sh.route_id = $2
+					OR EXISTS (
+						SELECT 1 FROM delivery_route_stops s
+						WHERE s.route_id = $2 AND s.shipment_id = sh.id
+							AND s.status NOT IN ('completed', 'skipped', 'failed')
+					)
+				)
+		)
+		SELECT rs.id, ` + loadColumns + `
+		FROM route_shipments rs
+		JOIN stock_moves m ON m.picking_id = rs.picking_id AND m.deleted_at IS NULL AND m.state <> 'cancel'
+		JOIN products pr ON pr.id = m.product_id
+		GROUP BY rs.id, 2
+	`
+	return r.findLoads(ctx, query, orgID, routeID)
+}
+
+// FindShipmentLoads returns the load of one shipment
+func (r *deliveryCapacityRepository) FindShipmentLoads(ctx context.Context, orgID, shipmentID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	query := `
+		SELECT sh.id, ` + loadColumns + `
+		FROM delivery_shipments sh
+		JOIN stock_moves m ON m.picking_id = sh.picking_id AND m.deleted_at IS NULL AND m.state <> 'cancel'
+		JOIN products pr ON pr.id = m.product_id
+		WHERE sh.organization_id = $1 AND sh.id = $2
+		GROUP BY sh.id, 2
+	`
+	return r.findLoads(ctx, query, orgID, shipmentID)
+}
+
+// FindPickingLoads returns the load of a picking that has no shipment yet
+func (r *deliveryCapacityRepository) FindPickingLoads(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	query := `
+		SELECT NULL::uuid, ` + loadColumns + `
+		FROM stock_moves m
+		JOIN products pr ON pr.id = m.product_id
+		WHERE m.organization_id = $1 AND m.picking_id = $2 AND m.deleted_at IS NULL AND m.state <> 'cancel'
+		GROUP BY 2
+	`
+	return r.findLoads(ctx, query, orgID, pickingID)
+}
+
+func (r *deliveryCapacityRepository) findLoads(ctx context.Context, query string, args ...interface{}) ([]deliverytypes.ShipmentLoad, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shipment loads: %w", err)
+	}
+	defer rows.Close()
+
+	var loads []deliverytypes.ShipmentLoad
+	for rows.Next() {
+		var load deliverytypes.ShipmentLoad
+		var shipmentID uuid.NullUUID
+
+		if err := rows.Scan(
+			&shipmentID,
+			&load.TemperatureClass,
+			&load.Weight,
+			&load.Volume,
+			&load.Unmeasured,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment load: %w", err)
+		}
+
+		if shipmentID.Valid {
+			load.ShipmentID = &shipmentID.UUID
+		}
+
+		loads = append(loads, load)
+	}
+
+	return loads, rows.Err()
+}
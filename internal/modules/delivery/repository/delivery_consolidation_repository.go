@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrConsolidationConflict is returned when shipments of a consolidation
+// changed since it was suggested
+var ErrConsolidationConflict = errors.New("shipments are no longer pending or were consolidated already")
+
+// DeliveryConsolidationRepository finds consolidation candidates and merges shipments
+type DeliveryConsolidationRepository interface {
+	GetSettings(ctx context.Context, orgID uuid.UUID) (*deliverytypes.ConsolidationSettings, error)
+	SaveSettings(ctx context.Context, orgID uuid.UUID, settings deliverytypes.ConsolidationSettings) error
+	ListAutoApplyOrganizations(ctx context.Context) ([]uuid.UUID, error)
+	FindCandidates(ctx context.Context, orgID uuid.UUID, shipmentIDs []uuid.UUID) ([]deliverytypes.ConsolidationCandidate, error)
+	ApplyConsolidation(ctx context.Context, consolidation deliverytypes.Consolidation, candidates []deliverytypes.ConsolidationCandidate) error
+	ListConsolidations(ctx context.Context, orgID uuid.UUID, limit int) ([]deliverytypes.Consolidation, error)
+}
+
+type deliveryConsolidationRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryConsolidationRepository(db *sql.DB) DeliveryConsolidationRepository {
+	return &deliveryConsolidationRepository{db: db}
+}
+
+// GetSettings returns the organization's consolidation settings, or nil when
+// it has none
+func (r *deliveryConsolidationRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*deliverytypes.ConsolidationSettings, error) {
+	query := `SELECT settings->'delivery_consolidation' FROM organizations WHERE id = $1 AND deleted_at IS NULL`
+
+	var settingsJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(&settingsJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get consolidation settings: %w", err)
+	}
+	if len(settingsJSON) == 0 {
+		return nil, nil
+	}
+
+	var settings deliverytypes.ConsolidationSettings
+	if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+		return nil, fmt.Errorf("invalid consolidation settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveSettings replaces the "delivery_consolidation" key of the
+// organization's settings, leaving other settings untouched
+func (r *deliveryConsolidationRepository) SaveSettings(ctx context.Context, orgID uuid.UUID, settings deliverytypes.ConsolidationSettings) error {
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consolidation settings: %w", err)
+	}
+
+	query := `
+		UPDATE organizations
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{delivery_consolidation}', $2::jsonb), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	if _, err := r.db.ExecContext(ctx, query, orgID, string(settingsJSON)); err != nil {
+		return fmt.Errorf("failed to save consolidation settings: %w", err)
+	}
+	return nil
+}
+
+// ListAutoApplyOrganizations returns the organizations that consolidate
+// same-address shipments automatically
+func (r *deliveryConsolidationRepository) ListAutoApplyOrganizations(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+		SELECT id FROM organizations
+		WHERE deleted_at IS NULL
+			AND (settings->'delivery_consolidation'->>'auto_apply')::boolean IS TRUE
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auto consolidation organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgIDs = append(orgIDs, id)
+	}
+	return orgIDs, rows.Err()
+}
+
+// candidateQuery selects pending outbound shipments that have not been merged
+// into another one, with the address of the picking's partner
+const candidateQuery = `
+	SELECT
+		sh.id, sh.route_id, COALESCE(sh.tracking_number, ''), p.partner_id,
+		COALESCE(c.street, ''), COALESCE(c.zip, ''), COALESCE(c.city, ''), c.country_id,
+		COALESCE(sh.estimated_departure_at, p.scheduled_date, sh.created_at),
+		COALESCE(load.weight, 0), COALESCE(load.volume, 0)
+	FROM delivery_shipments sh
+	JOIN stock_pickings p ON p.id = sh.picking_id
+	LEFT JOIN contacts c ON c.id = p.partner_id
+	LEFT JOIN LATERAL (
+		SELECT SUM(pr.weight * m.product_uom_qty) AS weight, SUM(pr.volume * m.product_uom_qty) AS volume
+		FROM stock_moves m
+		JOIN products pr ON pr.id = m.product_id
+		WHERE m.picking_id = sh.picking_id AND m.deleted_at IS NULL AND m.state <> 'cancel'
+	) load ON true
+	WHERE sh.organization_id = $1
+		AND sh.deleted_at IS NULL
+		AND sh.status IN ('draft', 'scheduled')
+		AND sh.shipment_type = 'outbound'
+		AND sh.departed_at IS NULL
+		AND sh.consolidated_into_id IS NULL
+`
+
+// FindCandidates returns the shipments that can be consolidated, limited to
+// shipmentIDs when given
+func (r *deliveryConsolidationRepository) FindCandidates(ctx context.Context, orgID uuid.UUID, shipmentIDs []uuid.UUID) ([]deliverytypes.ConsolidationCandidate, error) {
+	query := candidateQuery
+	args := []interface{}{orgID}
+	if len(shipmentIDs) > 0 {
+		query += ` AND sh.id = ANY($2::uuid[])`
+		args = append(args, pq.Array(uuidStrings(shipmentIDs)))
+	}
+	query += ` ORDER BY 9, sh.id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find consolidation candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []deliverytypes.ConsolidationCandidate
+	for rows.Next() {
+		var c deliverytypes.ConsolidationCandidate
+		var routeID, partnerID, countryID uuid.NullUUID
+		if err := rows.Scan(
+			&c.ShipmentID, &routeID, &c.TrackingNumber, &partnerID,
+			&c.Street, &c.Zip, &c.City, &countryID,
+			&c.PlannedAt, &c.Weight, &c.Volume,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consolidation candidate: %w", err)
+		}
+		if routeID.Valid {
+			c.RouteID = &routeID.UUID
+		}
+		if partnerID.Valid {
+			c.PartnerID = &partnerID.UUID
+		}
+		if countryID.Valid {
+			c.CountryID = &countryID.UUID
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// ApplyConsolidation merges the shipments and records the consolidation.
+// Same-address shipments are merged into the primary: they take its route
+// and their own planned stops are removed. Same-zone shipments move to the
+// primary's route with a new stop at its end.
+func (r *deliveryConsolidationRepository) ApplyConsolidation(ctx context.Context, consolidation deliverytypes.Consolidation, candidates []deliverytypes.ConsolidationCandidate) error {
+	var others []uuid.UUID
+	for _, id := range consolidation.ShipmentIDs {
+		if id != consolidation.PrimaryShipmentID {
+			others = append(others, id)
+		}
+	}
+	otherIDs := pq.Array(uuidStrings(others))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the shipments and make sure none changed since they were suggested
+	var pending int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM delivery_shipments
+			WHERE organization_id = $1 AND id = ANY($2::uuid[])
+				AND deleted_at IS NULL AND status IN ('draft', 'scheduled')
+				AND departed_at IS NULL AND consolidated_into_id IS NULL
+			FOR UPDATE
+		) locked
+	`, consolidation.OrganizationID, pq.Array(uuidStrings(consolidation.ShipmentIDs))).Scan(&pending)
+	if err != nil {
+		return fmt.Errorf("failed to lock shipments: %w", err)
+	}
+	if pending != len(consolidation.ShipmentIDs) {
+		return ErrConsolidationConflict
+	}
+
+	// Planned stops of the joining shipments on other routes go away
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM delivery_route_stops
+		WHERE organization_id = $1 AND shipment_id = ANY($2::uuid[]) AND status = 'planned'
+			AND ($3 = 'address' OR route_id IS DISTINCT FROM $4)
+	`, consolidation.OrganizationID, otherIDs, consolidation.Match, consolidation.RouteID); err != nil {
+		return fmt.Errorf("failed to remove consolidated stops: %w", err)
+	}
+
+	switch consolidation.Match {
+	case deliverytypes.ConsolidationMatchAddress:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE delivery_shipments
+			SET consolidated_into_id = $3, route_id = $4, updated_at = NOW(), updated_by = $5
+			WHERE organization_id = $1 AND id = ANY($2::uuid[])
+		`, consolidation.OrganizationID, otherIDs, consolidation.PrimaryShipmentID, consolidation.RouteID, consolidation.AppliedBy)
+		if err != nil {
+			return fmt.Errorf("failed to merge shipments: %w", err)
+		}
+	case deliverytypes.ConsolidationMatchZone:
+		for _, candidate := range candidates {
+			if candidate.ShipmentID == consolidation.PrimaryShipmentID ||
+				(candidate.RouteID != nil && consolidation.RouteID != nil && *candidate.RouteID == *consolidation.RouteID) {
+				continue
+			}
+			if err := r.moveToRoute(ctx, tx, consolidation, candidate); err != nil {
+				return err
+			}
+		}
+	}
+
+	savings, err := json.Marshal(consolidation.Savings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consolidation savings: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO delivery_shipment_consolidations (
+			id, organization_id, match_type, primary_shipment_id, shipment_ids, route_id, savings, automatic, applied_by, applied_at
+		) VALUES ($1, $2, $3, $4, $5::uuid[], $6, $7, $8, $9, $10)
+	`,
+		consolidation.ID, consolidation.OrganizationID, consolidation.Match, consolidation.PrimaryShipmentID,
+		pq.Array(uuidStrings(consolidation.ShipmentIDs)), consolidation.RouteID, savings,
+		consolidation.Automatic, consolidation.AppliedBy, consolidation.AppliedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record consolidation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit consolidation: %w", err)
+	}
+	return nil
+}
+
+// moveToRoute puts a shipment on the consolidation's route, as the route's last stop
+func (r *deliveryConsolidationRepository) moveToRoute(ctx context.Context, tx *sql.Tx, consolidation deliverytypes.Consolidation, candidate deliverytypes.ConsolidationCandidate) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE delivery_shipments SET route_id = $3, updated_at = NOW(), updated_by = $4
+		WHERE organization_id = $1 AND id = $2
+	`, consolidation.OrganizationID, candidate.ShipmentID, consolidation.RouteID, consolidation.AppliedBy); err != nil {
+		return fmt.Errorf("failed to move shipment to route: %w", err)
+	}
+
+	address, err := json.Marshal(map[string]string{
+		"street": candidate.Street,
+		"city":   candidate.City,
+		"zip":    candidate.Zip,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal stop address: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO delivery_route_stops (
+			id, organization_id, route_id, shipment_id, stop_sequence, contact_id, address, status, metadata, created_by
+		)
+		SELECT $1, $2, $3, $4, COALESCE(MAX(stop_sequence), 0) + 1, $5, $6, 'planned', $7, $8
+		FROM delivery_route_stops
+		WHERE route_id = $3
+	`,
+		uuid.New(), consolidation.OrganizationID, consolidation.RouteID, candidate.ShipmentID,
+		candidate.PartnerID, address, fmt.Sprintf(`{"consolidation_id": %q}`, consolidation.ID), consolidation.AppliedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add consolidated stop: %w", err)
+	}
+	return nil
+}
+
+// ListConsolidations returns the latest applied consolidations
+func (r *deliveryConsolidationRepository) ListConsolidations(ctx context.Context, orgID uuid.UUID, limit int) ([]deliverytypes.Consolidation, error) {
+	query := `
+		SELECT id, organization_id, match_type, primary_shipment_id, shipment_ids::text[], route_id, savings, automatic, applied_by, applied_at
+		FROM delivery_shipment_consolidations
+		WHERE organization_id = $1
+		ORDER BY applied_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consolidations: %w", err)
+	}
+	defer rows.Close()
+
+	consolidations := []deliverytypes.Consolidation{}
+	for rows.Next() {
+		var c deliverytypes.Consolidation
+		var shipmentIDs []string
+		var routeID, appliedBy uuid.NullUUID
+		var savings []byte
+		if err := rows.Scan(
+			&c.ID, &c.OrganizationID, &c.Match, &c.PrimaryShipmentID, pq.Array(&shipmentIDs),
+			&routeID, &savings, &c.Automatic, &appliedBy, &c.AppliedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consolidation: %w", err)
+		}
+		for _, id := range shipmentIDs {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid consolidated shipment ID: %w", err)
+			}
+			c.ShipmentIDs = append(c.ShipmentIDs, parsed)
+		}
+		if routeID.Valid {
+			c.RouteID = &routeID.UUID
+		}
+		if appliedBy.Valid {
+			c.AppliedBy = &appliedBy.UUID
+		}
+		if err := json.Unmarshal(savings, &c.Savings); err != nil {
+			return nil, fmt.Errorf("invalid consolidation savings: %w", err)
+		}
+		consolidations = append(consolidations, c)
+	}
+	return consolidations, rows.Err()
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
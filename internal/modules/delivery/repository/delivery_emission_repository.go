@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeliveryEmissionRepository loads what delivery CO2e estimates depend on
+type DeliveryEmissionRepository interface {
+	GetFactors(ctx context.Context, orgID uuid.UUID) (*deliverytypes.EmissionFactors, error)
+	SaveFactors(ctx context.Context, orgID uuid.UUID, factors deliverytypes.EmissionFactors) error
+	FindTrip(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.EmissionTrip, error)
+	FindTrips(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]deliverytypes.EmissionTrip, error)
+	FindCarrierShipments(ctx context.Context, orgID uuid.UUID, from, to time.Time, customerID *uuid.UUID) ([]deliverytypes.EmissionShipment, error)
+}
+
+type deliveryEmissionRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryEmissionRepository(db *sql.DB) DeliveryEmissionRepository {
+	return &deliveryEmissionRepository{
+		db: db,
+	}
+}
+
+// GetFactors returns the organization's emission factors, or nil when it has none
+func (r *deliveryEmissionRepository) GetFactors(ctx context.Context, orgID uuid.UUID) (*deliverytypes.EmissionFactors, error) {
+	query := `SELECT settings->'delivery_emission_factors' FROM organizations WHERE id = $1 AND deleted_at IS NULL`
+
+	var factorsJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(&factorsJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get emission factors: %w", err)
+	}
+	if len(factorsJSON) == 0 {
+		return nil, nil
+	}
+
+	var factors deliverytypes.EmissionFactors
+	if err := json.Unmarshal(factorsJSON, &factors); err != nil {
+		return nil, fmt.Errorf("invalid emission factors: %w", err)
+	}
+	return &factors, nil
+}
+
+// SaveFactors replaces the "delivery_emission_factors" key of the
+// organization's settings, leaving other settings untouched
+func (r *deliveryEmissionRepository) SaveFactors(ctx context.Context, orgID uuid.UUID, factors deliverytypes.EmissionFactors) error {
+	factorsJSON, err := json.Marshal(factors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emission factors: %w", err)
+	}
+
+	query := `
+		UPDATE organizations
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{delivery_emission_factors}', $2::jsonb), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	if _, err := r.db.ExecContext(ctx, query, orgID, string(factorsJSON)); err != nil {
+		return fmt.Errorf("failed to save emission factors: %w", err)
+	}
+	return nil
+}
+
+// metadataNumber reads a non-negative number from a jsonb metadata key,
+// ignoring values that are not numbers
+const metadataNumber = `CASE WHEN %[1]s->>'%[2]s' ~ '^[0-9]+(\.[0-9]+)?$' THEN (%[1]s->>'%[2]s')::float8 END`
+
+// tripQuery selects routes with the type of their latest assigned vehicle
+// and the distance driven according to GPS positions
+var tripQuery = `
+	SELECT
+		r.id, COALESCE(r.route_code, ''), r.name, COALESCE(v.vehicle_type, ''),
+		` + fmt.Sprintf(metadataNumber, "v.metadata", deliverytypes.VehicleEmissionKey) + `,
+		COALESCE(gps.distance_km, 0),
+		` + fmt.Sprintf(metadataNumber, "r.metadata", deliverytypes.DistanceKmKey) + `,
+		COALESCE(r.actual_end_at, r.actual_start_at, r.scheduled_start_at, r.created_at)
+	FROM delivery_routes r
+	LEFT JOIN LATERAL (
+		SELECT a.vehicle_id
+		FROM delivery_route_assignments a
+		WHERE a.route_id = r.id AND a.vehicle_id IS NOT NULL AND a.assignment_status <> 'declined'
+		ORDER BY a.assigned_at DESC
+		LIMIT 1
+	) assignment ON true
+	LEFT JOIN delivery_vehicles v ON v.id = assignment.vehicle_id
+	LEFT JOIN LATERAL (
+		SELECT SUM(2 * 6371 * asin(LEAST(1, sqrt(
+			power(sin(radians(lat - prev_lat) / 2), 2) +
+			cos(radians(prev_lat)) * cos(radians(lat)) * power(sin(radians(lon - prev_lon) / 2), 2)
+		)))) AS distance_km
+		FROM (
+			SELECT
+				latitude::float8 AS lat, longitude::float8 AS lon,
+				LAG(latitude::float8) OVER (ORDER BY recorded_at) AS prev_lat,
+				LAG(longitude::float8) OVER (ORDER BY recorded_at) AS prev_lon
+			FROM delivery_route_positions
+			WHERE route_id = r.id
+		) positions
+		WHERE prev_lat IS NOT NULL
+	) gps ON true
+	WHERE r.organization_id = $1 AND r.deleted_at IS NULL
+`
+
+// shipmentQuery selects shipments with their customer and weight
+var shipmentQuery = `
+	SELECT
+		sh.id, sh.route_id, COALESCE(sh.tracking_number, ''), p.partner_id, COALESCE(c.name, ''),
+		COALESCE(sh.carrier_code, ''), COALESCE(load.weight, 0),
+		` + fmt.Sprintf(metadataNumber, "sh.metadata", deliverytypes.DistanceKmKey) + `,
+		COALESCE(sh.arrived_at, sh.departed_at, sh.estimated_departure_at, sh.created_at)
+	FROM delivery_shipments sh
+	JOIN stock_pickings p ON p.id = sh.picking_id
+	LEFT JOIN contacts c ON c.id = p.partner_id
+	LEFT JOIN LATERAL (
+		SELECT SUM(pr.weight * m.product_uom_qty) AS weight
+		FROM stock_moves m
+		JOIN products pr ON pr.id = m.product_id
+		WHERE m.picking_id = sh.picking_id AND m.deleted_at IS NULL AND m.state <> 'cancel'
+	) load ON true
+	WHERE sh.organization_id = $1 AND sh.deleted_at IS NULL AND sh.status <> 'cancelled'
+`
+
+// FindTrip returns a route with its shipments, or nil when it does not exist
+func (r *deliveryEmissionRepository) FindTrip(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.EmissionTrip, error) {
+	trips, err := r.findTrips(ctx, tripQuery+` AND r.id = $2`, orgID, routeID)
+	if err != nil || len(trips) == 0 {
+		return nil, err
+	}
+	return &trips[0], nil
+}
+
+// FindTrips returns the started or completed routes of the date range with
+// their shipments
+func (r *deliveryEmissionRepository) FindTrips(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]deliverytypes.EmissionTrip, error) {
+	query := tripQuery + `
+		AND r.status IN ('in_progress', 'completed')
+		AND COALESCE(r.actual_end_at, r.actual_start_at, r.scheduled_start_at, r.created_at) >= $2
+		AND COALESCE(r.actual_end_at, r.actual_start_at, r.scheduled_start_at, r.created_at) < $3
+		ORDER BY 8, r.id
+	`
+	return r.findTrips(ctx, query, orgID, from, to)
+}
+
+func (r *deliveryEmissionRepository) findTrips(ctx context.Context, query string, orgID uuid.UUID, args ...interface{}) ([]deliverytypes.EmissionTrip, error) {
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{orgID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find emission trips: %w", err)
+	}
+	defer rows.Close()
+
+	var trips []deliverytypes.EmissionTrip
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var trip deliverytypes.EmissionTrip
+		var vehicleFactor, plannedDistance sql.NullFloat64
+		if err := rows.Scan(
+			&trip.RouteID, &trip.RouteCode, &trip.RouteName, &trip.VehicleType,
+			&vehicleFactor, &trip.GPSDistanceKm, &plannedDistance, &trip.Date,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan emission trip: %w", err)
+		}
+		if vehicleFactor.Valid {
+			trip.VehicleKgPerKm = &vehicleFactor.Float64
+		}
+		if plannedDistance.Valid {
+			trip.PlannedDistanceKm = &plannedDistance.Float64
+		}
+		trip.Shipments = []deliverytypes.EmissionShipment{}
+		index[trip.RouteID] = len(trips)
+		trips = append(trips, trip)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(trips) == 0 {
+		return trips, nil
+	}
+
+	routeIDs := make([]uuid.UUID, 0, len(trips))
+	for _, trip := range trips {
+		routeIDs = append(routeIDs, trip.RouteID)
+	}
+	shipments, err := r.findShipments(ctx, shipmentQuery+` AND sh.route_id = ANY($2::uuid[]) ORDER BY sh.id`, orgID, pq.Array(uuidStrings(routeIDs)))
+	if err != nil {
+		return nil, err
+	}
+	for _, shipment := range shipments {
+		i := index[*shipment.RouteID]
+		trips[i].Shipments = append(trips[i].Shipments, shipment)
+	}
+	return trips, nil
+}
+
+// FindCarrierShipments returns the outbound shipments of the date range that
+// left without one of the organization's routes, optionally for one customer
+func (r *deliveryEmissionRepository) FindCarrierShipments(ctx context.Context, orgID uuid.UUID, from, to time.Time, customerID *uuid.UUID) ([]deliverytypes.EmissionShipment, error) {
+	query := shipmentQuery + `
+		AND sh.route_id IS NULL
+		AND sh.shipment_type = 'outbound'
+		AND sh.status IN ('in_transit', 'delivered')
+		AND COALESCE(sh.arrived_at, sh.departed_at, sh.estimated_departure_at, sh.created_at) >= $2
+		AND COALESCE(sh.arrived_at, sh.departed_at, sh.estimated_departure_at, sh.created_at) < $3
+	`
+	args := []interface{}{from, to}
+	if customerID != nil {
+		query += ` AND p.partner_id = $4`
+		args = append(args, *customerID)
+	}
+	query += ` ORDER BY 9, sh.id`
+
+	return r.findShipments(ctx, query, orgID, args...)
+}
+
+func (r *deliveryEmissionRepository) findShipments(ctx context.Context, query string, orgID uuid.UUID, args ...interface{}) ([]deliverytypes.EmissionShipment, error) {
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{orgID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find emission shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []deliverytypes.EmissionShipment
+	for rows.Next() {
+		var s deliverytypes.EmissionShipment
+		var routeID, customerID uuid.NullUUID
+		var distance sql.NullFloat64
+		if err := rows.Scan(
+			&s.ShipmentID, &routeID, &s.TrackingNumber, &customerID, &s.CustomerName,
+			&s.CarrierCode, &s.WeightKg, &distance, &s.Date,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan emission shipment: %w", err)
+		}
+		if routeID.Valid {
+			s.RouteID = &routeID.UUID
+		}
+		if customerID.Valid {
+			s.CustomerID = &customerID.UUID
+		}
+		if distance.Valid {
+			s.DistanceKm = &distance.Float64
+		}
+		shipments = append(shipments, s)
+	}
+	return shipments, rows.Err()
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryManifestRepository reads everything a route manifest prints
+type DeliveryManifestRepository interface {
+	FindRoute(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.DeliveryRoute, error)
+	FindRouteVehicle(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.ManifestVehicle, error)
+	FindManifestStops(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ManifestStop, error)
+	FindManifestItems(ctx context.Context, orgID, routeID uuid.UUID) (map[uuid.UUID][]deliverytypes.ManifestItem, error)
+}
+
+type deliveryManifestRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryManifestRepository(db *sql.DB) DeliveryManifestRepository {
+	return &deliveryManifestRepository{db: db}
+}
+
+// FindRoute returns the route header, or nil when the organization has no such route
+func (r *deliveryManifestRepository) FindRoute(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.DeliveryRoute, error) {
+	query := `
+		SELECT id, organization_id, name, COALESCE(route_code, ''), status, scheduled_start_at, COALESCE(notes, '')
+		FROM delivery_routes
+		WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+	`
+
+	var route deliverytypes.DeliveryRoute
+	var scheduledStartAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, orgID, routeID).Scan(
+		&route.ID,
+		&route.OrganizationID,
+		&route.Name,
+		&route.RouteCode,
+		&route.Status,
+		&scheduledStartAt,
+		&route.Notes,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find delivery route: %w", err)
+	}
+
+	if scheduledStartAt.Valid {
+		route.ScheduledStartAt = &scheduledStartAt.Time
+	}
+
+	return &route, nil
+}
+
+// FindRouteVehicle returns the vehicle of the route's latest open assignment,
+// or nil when no vehicle is assigned
+func (r *deliveryManifestRepository) FindRouteVehicle(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.ManifestVehicle, error) {
+	query := `
+		SELECT v.id, v.name, COALESCE(v.registration_number, ''), COALESCE(v.vehicle_type, 'other'), COALESCE(v.capacity, 0)
+		FROM delivery_route_assignments a
+		JOIN delivery_vehicles v ON v.id = a.vehicle_id AND v.deleted_at IS NULL
+		WHERE a.organization_id = $1 AND a.route_id = $2
+			AND a.assignment_status IN ('assigned', 'accepted', 'completed')
+		ORDER BY a.assigned_at DESC
+		LIMIT 1
+	`
+
+	var vehicle deliverytypes.ManifestVehicle
+	err := r.db.QueryRowContext(ctx, query, orgID, routeID).Scan(
+		&vehicle.ID,
+		&vehicle.Name,
+		&vehicle.RegistrationNumber,
+		&vehicle.VehicleType,
+		&vehicle.Capacity,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find route vehicle: %w", err)
+	}
+
+	return &vehicle, nil
+}
+
+// FindManifestStops returns the route's stops in delivery sequence, without items
+func (r *deliveryManifestRepository) FindManifestStops(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ManifestStop, error) {
+	query := `
+		SELECT
+			s.id, s.stop_sequence, s.status, COALESCE(c.display_name, c.name, ''), s.address,
+			s.planned_arrival_at, s.shipment_id, COALESCE(sh.tracking_number, ''), COALESCE(p.name, ''),
+			COALESCE(sh.requires_signature, false), COALESCE(s.notes, '')
+		FROM delivery_route_stops s
+		LEFT JOIN contacts c ON c.id = s.contact_id
+		LEFT JOIN delivery_shipments sh ON sh.id = s.shipment_id AND sh.deleted_at IS NULL
+		LEFT JOIN stock_pickings p ON p.id = sh.picking_id
+		WHERE s.organization_id = $1 AND s.route_id = $2
+		ORDER BY s.stop_sequence
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find route stops: %w", err)
+	}
+	defer rows.Close()
+
+	var stops []deliverytypes.ManifestStop
+	for rows.Next() {
+		var stop deliverytypes.ManifestStop
+		var address []byte
+		var plannedArrivalAt sql.NullTime
+		var shipmentID uuid.NullUUID
+
+		if err := rows.Scan(
+			&stop.StopID,
+			&stop.Sequence,
+			&stop.Status,
+			&stop.ContactName,
+			&address,
+			&plannedArrivalAt,
+			&shipmentID,
+			&stop.TrackingNumber,
+			&stop.PickingName,
+			&stop.RequiresSignature,
+			&stop.Notes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan route stop: %w", err)
+		}
+
+		if len(address) > 0 {
+			if err := json.Unmarshal(address, &stop.Address); err != nil {
+				return nil, fmt.Errorf("invalid address of stop %s: %w", stop.StopID, err)
+			}
+		}
+		if plannedArrivalAt.Valid {
+			stop.PlannedArrivalAt = &plannedArrivalAt.Time
+		}
+		if shipmentID.Valid {
+			stop.ShipmentID = &shipmentID.UUID
+		}
+
+		stops = append(stops, stop)
+	}
+
+	return stops, rows.Err()
+}
+
+// FindManifestItems returns the product lines of each stop's shipment, keyed
+// by stop ID. Weights and volumes are per product unit times the moved
+// quantity; cancelled moves are left out.
+func (r *deliveryManifestRepository) FindManifestItems(ctx context.Context, orgID, routeID uuid.UUID) (map[uuid.UUID][]deliverytypes.ManifestItem, error) {
+	query := `
+		SELECT
+			s.id, m.product_id, pr.name, COALESCE(pr.default_code, ''), m.product_uom_qty,
+			pr.weight * m.product_uom_qty, pr.volume * m.product_uom_qty,
+			COALESCE(pr.hazmat_class, ''), COALESCE(pr.un_number, ''), COALESCE(pr.hazmat_notes, '')
+		FROM delivery_route_stops s
+		JOIN delivery_shipments sh ON sh.id = s.shipment_id AND sh.deleted_at IS NULL
+		JOIN stock_moves m ON m.picking_id = sh.picking_id AND m.deleted_at IS NULL AND m.state <> 'cancel'
+		JOIN products pr ON pr.id = m.product_id
+		WHERE s.organization_id = $1 AND s.route_id = $2
+		ORDER BY s.stop_sequence, m.sequence, pr.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find manifest items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[uuid.UUID][]deliverytypes.ManifestItem)
+	for rows.Next() {
+		var stopID uuid.UUID
+		var item deliverytypes.ManifestItem
+		var weight, volume sql.NullFloat64
+
+		if err := rows.Scan(
+			&stopID,
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductCode,
+			&item.Quantity,
+			&weight,
+			&volume,
+			&item.HazmatClass,
+			&item.UNNumber,
+			&item.HazmatNotes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan manifest item: %w", err)
+		}
+
+		if weight.Valid {
+			item.Weight = &weight.Float64
+		}
+		if volume.Valid {
+			item.Volume = &volume.Float64
+		}
+
+		items[stopID] = append(items[stopID], item)
+	}
+
+	return items, rows.Err()
+}
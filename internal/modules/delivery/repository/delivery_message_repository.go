@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeliveryMessageRepository stores the messages of routes and their read receipts
+type DeliveryMessageRepository interface {
+	FindRouteParticipants(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.RouteParticipants, error)
+	StopBelongsToRoute(ctx context.Context, orgID, routeID, stopID uuid.UUID) (bool, error)
+	FindAttachmentMimeType(ctx context.Context, orgID, attachmentID uuid.UUID) (string, error)
+	CreateMessage(ctx context.Context, message deliverytypes.DeliveryMessage) (*deliverytypes.DeliveryMessage, error)
+	FindMessages(ctx context.Context, filter deliverytypes.DeliveryMessageFilter) ([]deliverytypes.DeliveryMessage, error)
+	MarkRead(ctx context.Context, orgID, routeID, userID uuid.UUID, messageIDs []uuid.UUID, readAt time.Time) ([]uuid.UUID, error)
+}
+
+type deliveryMessageRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryMessageRepository(db *sql.DB) DeliveryMessageRepository {
+	return &deliveryMessageRepository{db: db}
+}
+
+// FindRouteParticipants returns who takes part in a route's conversation, or
+// nil when the organization has no such route
+func (r *deliveryMessageRepository) FindRouteParticipants(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.RouteParticipants, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM delivery_routes
+			WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+		)
+	`, orgID, routeID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delivery route: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	query := `
+		SELECT e.user_id, 'driver'
+		FROM delivery_route_assignments a
+		JOIN employees e ON e.id = a.driver_employee_id
+		WHERE a.organization_id = $1 AND a.route_id = $2
+			AND a.assignment_status IN ('assigned', 'accepted')
+			AND e.user_id IS NOT NULL
+		UNION
+		SELECT created_by, 'dispatcher'
+		FROM delivery_routes
+		WHERE organization_id = $1 AND id = $2 AND created_by IS NOT NULL
+		UNION
+		SELECT sender_id, 'dispatcher'
+		FROM delivery_messages
+		WHERE organization_id = $1 AND route_id = $2 AND sender_role = 'dispatcher'
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find route participants: %w", err)
+	}
+	defer rows.Close()
+
+	participants := &deliverytypes.RouteParticipants{}
+	for rows.Next() {
+		var userID uuid.UUID
+		var role deliverytypes.MessageSenderRole
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan route participant: %w", err)
+		}
+		if role == deliverytypes.MessageSenderDriver {
+			participants.DriverUserIDs = append(participants.DriverUserIDs, userID)
+		} else {
+			participants.DispatcherUserIDs = append(participants.DispatcherUserIDs, userID)
+		}
+	}
+
+	return participants, rows.Err()
+}
+
+// StopBelongsToRoute reports whether the stop is one of the route's
+func (r *deliveryMessageRepository) StopBelongsToRoute(ctx context.Context, orgID, routeID, stopID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM delivery_route_stops
+			WHERE organization_id = $1 AND route_id = $2 AND id = $3
+		)
+	`, orgID, routeID, stopID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to find route stop: %w", err)
+	}
+	return exists, nil
+}
+
+// FindAttachmentMimeType returns the MIME type of an attachment, or an empty
+// string when the organization has no such attachment
+func (r *deliveryMessageRepository) FindAttachmentMimeType(ctx context.Context, orgID, attachmentID uuid.UUID) (string, error) {
+	var mimeType string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT mimetype FROM attachments
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, attachmentID).Scan(&mimeType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to find attachment: %w", err)
+	}
+	return mimeType, nil
+}
+
+func (r *deliveryMessageRepository) CreateMessage(ctx context.Context, message deliverytypes.DeliveryMessage) (*deliverytypes.DeliveryMessage, error) {
+	query := `
+		INSERT INTO delivery_messages (
+			id, organization_id, route_id, stop_id, sender_id, sender_role, body, attachment_id, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		message.ID,
+		message.OrganizationID,
+		message.RouteID,
+		message.StopID,
+		message.SenderID,
+		message.SenderRole,
+		message.Body,
+		message.AttachmentID,
+		message.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery message: %w", err)
+	}
+
+	if message.Reads == nil {
+		message.Reads = []deliverytypes.MessageRead{}
+	}
+	return &message, nil
+}
+
+// FindMessages returns a page of a route's messages, newest first, with their read receipts
+func (r *deliveryMessageRepository) FindMessages(ctx context.Context, filter deliverytypes.DeliveryMessageFilter) ([]deliverytypes.DeliveryMessage, error) {
+	query := `
+		SELECT id, organization_id, route_id, stop_id, sender_id, sender_role, body, attachment_id, created_at
+		FROM delivery_messages
+		WHERE organization_id = $1 AND route_id = $2
+	`
+	args := []interface{}{filter.OrganizationID, filter.RouteID}
+	if filter.StopID != nil {
+		args = append(args, *filter.StopID)
+		query += fmt.Sprintf(" AND stop_id = $%d", len(args))
+	}
+	if filter.Before != nil {
+		args = append(args, *filter.Before)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delivery messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []deliverytypes.DeliveryMessage{}
+	index := make(map[uuid.UUID]int)
+	var ids []string
+	for rows.Next() {
+		var message deliverytypes.DeliveryMessage
+		var stopID, attachmentID uuid.NullUUID
+		if err := rows.Scan(
+			&message.ID,
+			&message.OrganizationID,
+			&message.RouteID,
+			&stopID,
+			&message.SenderID,
+			&message.SenderRole,
+			&message.Body,
+			&attachmentID,
+			&message.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery message: %w", err)
+		}
+		if stopID.Valid {
+			message.StopID = &stopID.UUID
+		}
+		if attachmentID.Valid {
+			message.AttachmentID = &attachmentID.UUID
+		}
+		message.Reads = []deliverytypes.MessageRead{}
+		index[message.ID] = len(messages)
+		ids = append(ids, message.ID.String())
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	readRows, err := r.db.QueryContext(ctx, `
+		SELECT message_id, user_id, read_at
+		FROM delivery_message_reads
+		WHERE organization_id = $1 AND message_id = ANY($2::uuid[])
+		ORDER BY read_at
+	`, filter.OrganizationID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message reads: %w", err)
+	}
+	defer readRows.Close()
+
+	for readRows.Next() {
+		var read deliverytypes.MessageRead
+		if err := readRows.Scan(&read.MessageID, &read.UserID, &read.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message read: %w", err)
+		}
+		i := index[read.MessageID]
+		messages[i].Reads = append(messages[i].Reads, read)
+	}
+
+	return messages, readRows.Err()
+}
+
+// MarkRead records that the user read messages of the route written by
+// others, all of them when messageIDs is empty. It returns the messages that
+// were not read before.
+func (r *deliveryMessageRepository) MarkRead(ctx context.Context, orgID, routeID, userID uuid.UUID, messageIDs []uuid.UUID, readAt time.Time) ([]uuid.UUID, error) {
+	query := `
+		INSERT INTO delivery_message_reads (message_id, organization_id, user_id, read_at)
+		SELECT id, organization_id, $3, $4
+		FROM delivery_messages
+		WHERE organization_id = $1 AND route_id = $2 AND sender_id <> $3
+	`
+	args := []interface{}{orgID, routeID, userID, readAt}
+	if len(messageIDs) > 0 {
+		ids := make([]string, len(messageIDs))
+		for i, id := range messageIDs {
+			ids[i] = id.String()
+		}
+		args = append(args, pq.Array(ids))
+		query += ` AND id = ANY($5::uuid[])`
+	}
+	query += `
+		ON CONFLICT (message_id, user_id) DO NOTHING
+		RETURNING message_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	defer rows.Close()
+
+	marked := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan read message: %w", err)
+		}
+		marked = append(marked, id)
+	}
+	return marked, rows.Err()
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryProofRepository reads what a proof of delivery prints and finds
+// the delivered shipments to print them for
+type DeliveryProofRepository interface {
+	FindProof(ctx context.Context, orgID, shipmentID uuid.UUID) (*deliverytypes.DeliveryProof, error)
+	FindProofItems(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ManifestItem, error)
+	ListDeliveredShipments(ctx context.Context, orgID uuid.UUID, routeID *uuid.UUID, from, to *time.Time, limit int) ([]deliverytypes.DeliveredShipment, error)
+}
+
+type deliveryProofRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryProofRepository(db *sql.DB) DeliveryProofRepository {
+	return &deliveryProofRepository{db: db}
+}
+
+// FindProof returns the shipment with its stop, route and latest delivered
+// event, or nil when the organization has no such shipment. The recipient
+// is the stop's contact, falling back to the picking's partner.
+func (r *deliveryProofRepository) FindProof(ctx context.Context, orgID, shipmentID uuid.UUID) (*deliverytypes.DeliveryProof, error) {
+	query := `
+		SELECT
+			sh.id, sh.organization_id, COALESCE(sh.tracking_number, ''), COALESCE(sh.carrier_name, ''), sh.status,
+			sh.picking_id, COALESCE(p.name, ''),
+			COALESCE(sc.display_name, sc.name, pc.display_name, pc.name, ''), st.address,
+			sh.route_id, COALESCE(rt.name, ''), COALESCE(rt.route_code, ''), st.stop_sequence, COALESCE(st.notes, ''),
+			COALESCE(sh.requires_signature, false),
+			COALESCE(ev.event_time, st.actual_arrival_at, sh.arrived_at), COALESCE(ev.message, ''), ev.raw_payload,
+			ev.latitude, ev.longitude
+		FROM delivery_shipments sh
+		JOIN stock_pickings p ON p.id = sh.picking_id
+		LEFT JOIN contacts pc ON pc.id = p.partner_id
+		LEFT JOIN delivery_routes rt ON rt.id = sh.route_id
+		LEFT JOIN LATERAL (
+			SELECT s.address, s.stop_sequence, s.notes, s.contact_id, s.actual_arrival_at
+			FROM delivery_route_stops s
+			WHERE s.shipment_id = sh.id
+			ORDER BY s.actual_arrival_at DESC NULLS LAST, s.stop_sequence DESC
+			LIMIT 1
+		) st ON true
+		LEFT JOIN contacts sc ON sc.id = st.contact_id
+		LEFT JOIN LATERAL (
+			SELECT e.event_time, e.message, e.raw_payload, e.latitude, e.longitude
+			FROM delivery_tracking_events e
+			WHERE e.shipment_id = sh.id AND e.status = 'delivered'
+			ORDER BY e.event_time DESC
+			LIMIT 1
+		) ev ON true
+		WHERE sh.organization_id = $1 AND sh.id = $2 AND sh.deleted_at IS NULL
+	`
+
+	var proof deliverytypes.DeliveryProof
+	var address, payload []byte
+	var routeID uuid.NullUUID
+	var stopSequence sql.NullInt64
+	var deliveredAt sql.NullTime
+	var latitude, longitude sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, query, orgID, shipmentID).Scan(
+		&proof.ShipmentID,
+		&proof.OrganizationID,
+		&proof.TrackingNumber,
+		&proof.CarrierName,
+		&proof.Status,
+		&proof.PickingID,
+		&proof.PickingName,
+		&proof.RecipientName,
+		&address,
+		&routeID,
+		&proof.RouteName,
+		&proof.RouteCode,
+		&stopSequence,
+		&proof.StopNotes,
+		&proof.RequiresSignature,
+		&deliveredAt,
+		&proof.DeliveryMessage,
+		&payload,
+		&latitude,
+		&longitude,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find proof of delivery: %w", err)
+	}
+
+	if len(address) > 0 {
+		if err := json.Unmarshal(address, &proof.Address); err != nil {
+			return nil, fmt.Errorf("failed to decode stop address: %w", err)
+		}
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &proof.DeliveryDetails); err != nil {
+			return nil, fmt.Errorf("failed to decode delivered event: %w", err)
+		}
+	}
+	if routeID.Valid {
+		proof.RouteID = &routeID.UUID
+	}
+	if stopSequence.Valid {
+		sequence := int(stopSequence.Int64)
+		proof.StopSequence = &sequence
+	}
+	if deliveredAt.Valid {
+		proof.DeliveredAt = &deliveredAt.Time
+	}
+	if latitude.Valid && longitude.Valid {
+		proof.Latitude = &latitude.Float64
+		proof.Longitude = &longitude.Float64
+	}
+
+	return &proof, nil
+}
+
+// FindProofItems returns the product lines of the shipment's picking
+func (r *deliveryProofRepository) FindProofItems(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ManifestItem, error) {
+	query := `
+		SELECT
+			m.product_id, pr.name, COALESCE(pr.default_code, ''), m.product_uom_qty,
+			pr.weight * m.product_uom_qty, pr.volume * m.product_uom_qty,
+			COALESCE(pr.hazmat_class, ''), COALESCE(pr.un_number, ''), COALESCE(pr.hazmat_notes, '')
+		FROM stock_moves m
+		JOIN products pr ON pr.id = m.product_id
+		WHERE m.organization_id = $1 AND m.picking_id = $2 AND m.deleted_at IS NULL AND m.state <> 'cancel'
+		ORDER BY m.sequence, pr.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, pickingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find proof of delivery items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []deliverytypes.ManifestItem
+	for rows.Next() {
+		var item deliverytypes.ManifestItem
+		var weight, volume sql.NullFloat64
+
+		if err := rows.Scan(
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductCode,
+			&item.Quantity,
+			&weight,
+			&volume,
+			&item.HazmatClass,
+			&item.UNNumber,
+			&item.HazmatNotes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan proof of delivery item: %w", err)
+		}
+
+		if weight.Valid {
+			item.Weight = &weight.Float64
+		}
+		if volume.Valid {
+			item.Volume = &volume.Float64
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating proof of delivery items: %w", err)
+	}
+
+	return items, nil
+}
+
+// ListDeliveredShipments returns the delivered shipments of a route and, when
+// bounded, that arrived in [from, to), in order of arrival
+func (r *deliveryProofRepository) ListDeliveredShipments(ctx context.Context, orgID uuid.UUID, routeID *uuid.UUID, from, to *time.Time, limit int) ([]deliverytypes.DeliveredShipment, error) {
+	query := `
+		SELECT sh.id, COALESCE(sh.tracking_number, ''), COALESCE(p.name, '')
+		FROM delivery_shipments sh
+		JOIN stock_pickings p ON p.id = sh.picking_id
+		WHERE sh.organization_id = $1 AND sh.status = 'delivered' AND sh.deleted_at IS NULL
+	`
+	args := []interface{}{orgID}
+	if routeID != nil {
+		args = append(args, *routeID)
+		query += fmt.Sprintf(" AND sh.route_id = $%d", len(args))
+	}
+	if from != nil {
+		args = append(args, *from)
+		query += fmt.Sprintf(" AND sh.arrived_at >= $%d", len(args))
+	}
+	if to != nil {
+		args = append(args, *to)
+		query += fmt.Sprintf(" AND sh.arrived_at < $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY sh.arrived_at, sh.id LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delivered shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []deliverytypes.DeliveredShipment
+	for rows.Next() {
+		var shipment deliverytypes.DeliveredShipment
+		if err := rows.Scan(&shipment.ShipmentID, &shipment.TrackingNumber, &shipment.PickingName); err != nil {
+			return nil, fmt.Errorf("failed to scan delivered shipment: %w", err)
+		}
+		shipments = append(shipments, shipment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating delivered shipments: %w", err)
+	}
+
+	return shipments, nil
+}
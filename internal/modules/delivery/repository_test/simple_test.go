@@ -30,7 +30,6 @@ func TestDeliveryVehicleRepository_Create(t *testing.T) {
 	// This is a simple test to verify the repository can be instantiated
 	// In a real scenario, you would use a test database
 
-	mockDB := &MockDB{}
 	repo := deliveryrepository.NewDeliveryVehicleRepository(nil)
 
 	assert.NotNil(t, repo, "Repository should not be nil")
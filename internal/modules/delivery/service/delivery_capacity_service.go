@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCompartment is returned for compartment definitions that fail validation
+var ErrInvalidCompartment = errors.New("invalid compartment")
+
+// maxViolationShipments caps the shipments suggested for moving per violation
+const maxViolationShipments = 5
+
+// capacityTolerance absorbs floating point noise when comparing loads with limits
+const capacityTolerance = 1e-6
+
+type DeliveryCapacityService struct {
+	repo deliveryrepository.DeliveryCapacityRepository
+}
+
+func NewDeliveryCapacityService(repo deliveryrepository.DeliveryCapacityRepository) *DeliveryCapacityService {
+	return &DeliveryCapacityService{
+		repo: repo,
+	}
+}
+
+// GetRouteCapacity reports the route's load against its assigned vehicle
+func (s *DeliveryCapacityService) GetRouteCapacity(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.CapacityReport, error) {
+	vehicle, err := s.repo.FindRouteVehicleCapacity(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := s.repo.FindRouteLoads(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &deliverytypes.CapacityReport{
+		RouteID:    routeID,
+		Vehicle:    vehicle,
+		Usage:      []deliverytypes.CapacityUsage{},
+		Violations: []deliverytypes.CapacityViolation{},
+	}
+	for _, load := range loads {
+		report.Unmeasured += load.Unmeasured
+	}
+	if vehicle != nil {
+		report.Usage, report.Violations = evaluateCapacity(vehicle, loads)
+	}
+
+	return report, nil
+}
+
+// CheckRouteWithShipment returns the violations the route's vehicle would
+// have with the shipment on board
+func (s *DeliveryCapacityService) CheckRouteWithShipment(ctx context.Context, orgID, routeID, shipmentID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	vehicle, err := s.repo.FindRouteVehicleCapacity(ctx, orgID, routeID)
+	if err != nil || vehicle == nil {
+		return nil, err
+	}
+	extra, err := s.repo.FindShipmentLoads(ctx, orgID, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkWith(ctx, orgID, routeID, vehicle, extra)
+}
+
+// CheckRouteWithPicking returns the violations the route's vehicle would
+// have with a shipment of the picking on board
+func (s *DeliveryCapacityService) CheckRouteWithPicking(ctx context.Context, orgID, routeID, pickingID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	vehicle, err := s.repo.FindRouteVehicleCapacity(ctx, orgID, routeID)
+	if err != nil || vehicle == nil {
+		return nil, err
+	}
+	extra, err := s.repo.FindPickingLoads(ctx, orgID, pickingID)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkWith(ctx, orgID, routeID, vehicle, extra)
+}
+
+// CheckRouteWithVehicle returns the violations the route's current load
+// would have on another vehicle
+func (s *DeliveryCapacityService) CheckRouteWithVehicle(ctx context.Context, orgID, routeID, vehicleID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	vehicle, err := s.repo.FindVehicleCapacity(ctx, orgID, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if vehicle == nil {
+		return nil, fmt.Errorf("delivery vehicle %s not found", vehicleID)
+	}
+	return s.checkWith(ctx, orgID, routeID, vehicle, nil)
+}
+
+func (s *DeliveryCapacityService) checkWith(ctx context.Context, orgID, routeID uuid.UUID, vehicle *deliverytypes.VehicleCapacity, extra []deliverytypes.ShipmentLoad) ([]deliverytypes.CapacityViolation, error) {
+	loads, err := s.repo.FindRouteLoads(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A shipment already on the route is not counted twice
+	onRoute := make(map[uuid.UUID]bool)
+	for _, load := range loads {
+		if load.ShipmentID != nil {
+			onRoute[*load.ShipmentID] = true
+		}
+	}
+	for _, load := range extra {
+		if load.ShipmentID == nil || !onRoute[*load.ShipmentID] {
+			loads = append(loads, load)
+		}
+	}
+
+	_, violations := evaluateCapacity(vehicle, loads)
+	return violations, nil
+}
+
+// GetVehicleCapacity returns a vehicle's limits and compartments, or nil
+// when the organization has no such vehicle
+func (s *DeliveryCapacityService) GetVehicleCapacity(ctx context.Context, orgID, vehicleID uuid.UUID) (*deliverytypes.VehicleCapacity, error) {
+	return s.repo.FindVehicleCapacity(ctx, orgID, vehicleID)
+}
+
+// SetVehicleCompartments replaces the compartments of a vehicle. It returns
+// nil when the organization has no such vehicle.
+func (s *DeliveryCapacityService) SetVehicleCompartments(ctx context.Context, orgID, vehicleID uuid.UUID, compartments []deliverytypes.VehicleCompartment) ([]deliverytypes.VehicleCompartment, error) {
+	vehicle, err := s.repo.FindVehicleCapacity(ctx, orgID, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if vehicle == nil {
+		return nil, nil
+	}
+
+	names := make(map[string]bool)
+	for i := range compartments {
+		compartment := &compartments[i]
+		compartment.Name = strings.TrimSpace(compartment.Name)
+		if compartment.Name == "" {
+			return nil, fmt.Errorf("%w %d: name is required", ErrInvalidCompartment, i)
+		}
+		if names[strings.ToLower(compartment.Name)] {
+			return nil, fmt.Errorf("%w %q: defined twice", ErrInvalidCompartment, compartment.Name)
+		}
+		names[strings.ToLower(compartment.Name)] = true
+		if compartment.TemperatureClass == "" {
+			compartment.TemperatureClass = deliverytypes.TemperatureClassAmbient
+		}
+		if !compartment.TemperatureClass.IsValid() {
+			return nil, fmt.Errorf("%w %q: unknown temperature class %q", ErrInvalidCompartment, compartment.Name, compartment.TemperatureClass)
+		}
+		if (compartment.MaxWeight != nil && *compartment.MaxWeight < 0) || (compartment.MaxVolume != nil && *compartment.MaxVolume < 0) {
+			return nil, fmt.Errorf("%w %q: limits cannot be negative", ErrInvalidCompartment, compartment.Name)
+		}
+		compartment.ID = uuid.New()
+	}
+
+	return s.repo.ReplaceCompartments(ctx, orgID, vehicleID, compartments)
+}
+
+// classLoad accumulates the load of one temperature class
+type classLoad struct {
+	weight, volume float64
+	shipments      map[uuid.UUID]*[2]float64
+}
+
+func (c *classLoad) add(load deliverytypes.ShipmentLoad) {
+	c.weight += load.Weight
+	c.volume += load.Volume
+	if load.ShipmentID == nil {
+		return
+	}
+	if c.shipments == nil {
+		c.shipments = make(map[uuid.UUID]*[2]float64)
+	}
+	contribution, ok := c.shipments[*load.ShipmentID]
+	if !ok {
+		contribution = &[2]float64{}
+		c.shipments[*load.ShipmentID] = contribution
+	}
+	contribution[0] += load.Weight
+	contribution[1] += load.Volume
+}
+
+// largest returns the shipments contributing most to a metric (0 weight, 1 volume)
+func (c *classLoad) largest(metric int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(c.shipments))
+	for id, contribution := range c.shipments {
+		if contribution[metric] > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := c.shipments[ids[i]][metric], c.shipments[ids[j]][metric]
+		if a != b {
+			return a > b
+		}
+		return ids[i].String() < ids[j].String()
+	})
+	if len(ids) > maxViolationShipments {
+		ids = ids[:maxViolationShipments]
+	}
+	return ids
+}
+
+// evaluateCapacity compares loads with the vehicle's overall limits and with
+// the combined limits of its compartments per temperature class
+func evaluateCapacity(vehicle *deliverytypes.VehicleCapacity, loads []deliverytypes.ShipmentLoad) ([]deliverytypes.CapacityUsage, []deliverytypes.CapacityViolation) {
+	total := &classLoad{}
+	byClass := make(map[deliverytypes.TemperatureClass]*classLoad)
+	for _, load := range loads {
+		total.add(load)
+		if byClass[load.TemperatureClass] == nil {
+			byClass[load.TemperatureClass] = &classLoad{}
+		}
+		byClass[load.TemperatureClass].add(load)
+	}
+
+	compartments := vehicle.Compartments
+	if len(compartments) == 0 {
+		compartments = []deliverytypes.VehicleCompartment{{Name: vehicle.VehicleName, TemperatureClass: deliverytypes.TemperatureClassAmbient}}
+	}
+
+	usage := []deliverytypes.CapacityUsage{{
+		Weight:    total.weight,
+		Volume:    total.volume,
+		MaxWeight: vehicle.MaxWeight,
+		MaxVolume: vehicle.MaxVolume,
+	}}
+	violations := []deliverytypes.CapacityViolation{}
+
+	if v, ok := exceeded(total.weight, vehicle.MaxWeight); ok {
+		v.Code = deliverytypes.CapacityViolationVehicleWeight
+		v.Message = fmt.Sprintf("Load weighs %.2f, %.2f over the %.2f limit of %s", v.Load, v.Excess, v.Limit, vehicle.VehicleName)
+		v.Suggestion = fmt.Sprintf("Move at least %.2f of weight to another route or assign a vehicle with a higher weight limit", v.Excess)
+		v.ShipmentIDs = total.largest(0)
+		violations = append(violations, v)
+	}
+	if v, ok := exceeded(total.volume, vehicle.MaxVolume); ok {
+		v.Code = deliverytypes.CapacityViolationVehicleVolume
+		v.Message = fmt.Sprintf("Load takes %.2f of volume, %.2f over the %.2f limit of %s", v.Load, v.Excess, v.Limit, vehicle.VehicleName)
+		v.Suggestion = fmt.Sprintf("Move at least %.2f of volume to another route or assign a larger vehicle", v.Excess)
+		v.ShipmentIDs = total.largest(1)
+		violations = append(violations, v)
+	}
+
+	for _, class := range []deliverytypes.TemperatureClass{
+		deliverytypes.TemperatureClassAmbient, deliverytypes.TemperatureClassChilled, deliverytypes.TemperatureClassFrozen,
+	} {
+		load := byClass[class]
+		if load == nil {
+			load = &classLoad{}
+		}
+
+		var names []string
+		maxWeight, maxVolume := new(float64), new(float64)
+		for _, compartment := range compartments {
+			if compartment.TemperatureClass != class {
+				continue
+			}
+			names = append(names, compartment.Name)
+			maxWeight = addLimit(maxWeight, compartment.MaxWeight)
+			maxVolume = addLimit(maxVolume, compartment.MaxVolume)
+		}
+
+		if len(names) == 0 {
+			if load.weight > 0 || load.volume > 0 || len(load.shipments) > 0 {
+				violations = append(violations, deliverytypes.CapacityViolation{
+					Code:             deliverytypes.CapacityViolationNoCompartment,
+					TemperatureClass: class,
+					Load:             load.weight,
+					Excess:           load.weight,
+					Message:          fmt.Sprintf("%s has no %s compartment for %.2f of %s goods", vehicle.VehicleName, class, load.weight, class),
+					Suggestion:       fmt.Sprintf("Assign a vehicle with a %s compartment or move the %s shipments to another route", class, class),
+					ShipmentIDs:      load.largest(0),
+				})
+			}
+			continue
+		}
+
+		usage = append(usage, deliverytypes.CapacityUsage{
+			TemperatureClass: class,
+			Weight:           load.weight,
+			Volume:           load.volume,
+			MaxWeight:        maxWeight,
+			MaxVolume:        maxVolume,
+		})
+
+		compartmentNames := strings.Join(names, ", ")
+		if v, ok := exceeded(load.weight, maxWeight); ok {
+			v.Code = deliverytypes.CapacityViolationCompartmentWeight
+			v.TemperatureClass = class
+			v.Message = fmt.Sprintf("The %s load weighs %.2f, %.2f over the %.2f limit of %s", class, v.Load, v.Excess, v.Limit, compartmentNames)
+			v.Suggestion = fmt.Sprintf("Move at least %.2f of %s weight to another route or assign a vehicle with more %s capacity", v.Excess, class, class)
+			v.ShipmentIDs = load.largest(0)
+			violations = append(violations, v)
+		}
+		if v, ok := exceeded(load.volume, maxVolume); ok {
+			v.Code = deliverytypes.CapacityViolationCompartmentVolume
+			v.TemperatureClass = class
+			v.Message = fmt.Sprintf("The %s load takes %.2f of volume, %.2f over the %.2f limit of %s", class, v.Load, v.Excess, v.Limit, compartmentNames)
+			v.Suggestion = fmt.Sprintf("Move at least %.2f of %s volume to another route or assign a vehicle with more %s space", v.Excess, class, class)
+			v.ShipmentIDs = load.largest(1)
+			violations = append(violations, v)
+		}
+	}
+
+	return usage, violations
+}
+
+// addLimit sums compartment limits; any unlimited compartment makes the sum unlimited
+func addLimit(sum, limit *float64) *float64 {
+	if sum == nil || limit == nil {
+		return nil
+	}
+	total := *sum + *limit
+	return &total
+}
+
+func exceeded(load float64, limit *float64) (deliverytypes.CapacityViolation, bool) {
+	if limit == nil || load <= *limit+capacityTolerance {
+		return deliverytypes.CapacityViolation{}, false
+	}
+	return deliverytypes.CapacityViolation{Limit: *limit, Load: load, Excess: load - *limit}, true
+}
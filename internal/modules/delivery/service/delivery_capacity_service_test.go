@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCapacityRepo struct {
+	vehicle      *deliverytypes.VehicleCapacity
+	routeLoads   []deliverytypes.ShipmentLoad
+	extraLoads   []deliverytypes.ShipmentLoad
+	compartments []deliverytypes.VehicleCompartment
+}
+
+func (f *fakeCapacityRepo) FindVehicleCapacity(ctx context.Context, orgID, vehicleID uuid.UUID) (*deliverytypes.VehicleCapacity, error) {
+	if f.vehicle == nil || f.vehicle.VehicleID != vehicleID {
+		return nil, nil
+	}
+	return f.vehicle, nil
+}
+
+func (f *fakeCapacityRepo) FindRouteVehicleCapacity(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.VehicleCapacity, error) {
+	return f.vehicle, nil
+}
+
+func (f *fakeCapacityRepo) ReplaceCompartments(ctx context.Context, orgID, vehicleID uuid.UUID, compartments []deliverytypes.VehicleCompartment) ([]deliverytypes.VehicleCompartment, error) {
+	f.compartments = compartments
+	return compartments, nil
+}
+
+func (f *fakeCapacityRepo) FindRouteLoads(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	return append([]deliverytypes.ShipmentLoad(nil), f.routeLoads...), nil
+}
+
+func (f *fakeCapacityRepo) FindShipmentLoads(ctx context.Context, orgID, shipmentID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	return f.extraLoads, nil
+}
+
+func (f *fakeCapacityRepo) FindPickingLoads(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ShipmentLoad, error) {
+	return f.extraLoads, nil
+}
+
+func shipmentLoad(class deliverytypes.TemperatureClass, weight, volume float64) deliverytypes.ShipmentLoad {
+	id := uuid.New()
+	return deliverytypes.ShipmentLoad{ShipmentID: &id, TemperatureClass: class, Weight: weight, Volume: volume}
+}
+
+func violationCodes(violations []deliverytypes.CapacityViolation) []deliverytypes.CapacityViolationCode {
+	codes := make([]deliverytypes.CapacityViolationCode, len(violations))
+	for i, v := range violations {
+		codes[i] = v.Code
+	}
+	return codes
+}
+
+func TestEvaluateCapacityVehicleWeight(t *testing.T) {
+	vehicle := &deliverytypes.VehicleCapacity{VehicleName: "Van 1", MaxWeight: floatPtr(100)}
+	heavy := shipmentLoad(deliverytypes.TemperatureClassAmbient, 80, 1)
+	light := shipmentLoad(deliverytypes.TemperatureClassAmbient, 30, 1)
+
+	usage, violations := evaluateCapacity(vehicle, []deliverytypes.ShipmentLoad{light, heavy})
+
+	require.Len(t, usage, 2)
+	assert.Equal(t, 110.0, usage[0].Weight)
+	assert.Nil(t, usage[0].MaxVolume, "volume is unlimited")
+	assert.Equal(t, deliverytypes.TemperatureClassAmbient, usage[1].TemperatureClass)
+
+	require.Len(t, violations, 1)
+	assert.Equal(t, deliverytypes.CapacityViolationVehicleWeight, violations[0].Code)
+	assert.InDelta(t, 10.0, violations[0].Excess, 1e-9)
+	assert.Equal(t, []uuid.UUID{*heavy.ShipmentID, *light.ShipmentID}, violations[0].ShipmentIDs, "largest contributors come first")
+	assert.NotEmpty(t, violations[0].Suggestion)
+}
+
+func TestEvaluateCapacityCompartments(t *testing.T) {
+	vehicle := &deliverytypes.VehicleCapacity{
+		VehicleName: "Reefer",
+		MaxWeight:   floatPtr(1000),
+		Compartments: []deliverytypes.VehicleCompartment{
+			{Name: "Dry", TemperatureClass: deliverytypes.TemperatureClassAmbient, MaxWeight: floatPtr(600)},
+			{Name: "Chill A", TemperatureClass: deliverytypes.TemperatureClassChilled, MaxWeight: floatPtr(100), MaxVolume: floatPtr(2)},
+			{Name: "Chill B", TemperatureClass: deliverytypes.TemperatureClassChilled, MaxWeight: floatPtr(100), MaxVolume: floatPtr(2)},
+		},
+	}
+	loads := []deliverytypes.ShipmentLoad{
+		shipmentLoad(deliverytypes.TemperatureClassAmbient, 300, 10),
+		shipmentLoad(deliverytypes.TemperatureClassChilled, 150, 3),
+		shipmentLoad(deliverytypes.TemperatureClassChilled, 100, 0.5),
+	}
+
+	usage, violations := evaluateCapacity(vehicle, loads)
+
+	require.Len(t, usage, 3)
+	assert.Equal(t, 200.0, *usage[2].MaxWeight, "limits of compartments of a class add up")
+
+	require.Len(t, violations, 1)
+	assert.Equal(t, deliverytypes.CapacityViolationCompartmentWeight, violations[0].Code)
+	assert.Equal(t, deliverytypes.TemperatureClassChilled, violations[0].TemperatureClass)
+	assert.Equal(t, 50.0, violations[0].Excess)
+	assert.Contains(t, violations[0].Message, "Chill A, Chill B")
+	assert.Len(t, violations[0].ShipmentIDs, 2)
+}
+
+func TestEvaluateCapacityNoCompartment(t *testing.T) {
+	vehicle := &deliverytypes.VehicleCapacity{VehicleName: "Van 1"}
+	frozen := shipmentLoad(deliverytypes.TemperatureClassFrozen, 20, 1)
+
+	_, violations := evaluateCapacity(vehicle, []deliverytypes.ShipmentLoad{
+		shipmentLoad(deliverytypes.TemperatureClassAmbient, 500, 50),
+		frozen,
+	})
+
+	require.Len(t, violations, 1, "a vehicle without limits carries any ambient load")
+	assert.Equal(t, deliverytypes.CapacityViolationNoCompartment, violations[0].Code)
+	assert.Equal(t, deliverytypes.TemperatureClassFrozen, violations[0].TemperatureClass)
+	assert.Equal(t, []uuid.UUID{*frozen.ShipmentID}, violations[0].ShipmentIDs)
+}
+
+func TestEvaluateCapacityUnlimitedCompartment(t *testing.T) {
+	vehicle := &deliverytypes.VehicleCapacity{
+		VehicleName: "Truck",
+		Compartments: []deliverytypes.VehicleCompartment{
+			{Name: "Front", TemperatureClass: deliverytypes.TemperatureClassAmbient, MaxWeight: floatPtr(10)},
+			{Name: "Rear", TemperatureClass: deliverytypes.TemperatureClassAmbient},
+		},
+	}
+
+	usage, violations := evaluateCapacity(vehicle, []deliverytypes.ShipmentLoad{shipmentLoad(deliverytypes.TemperatureClassAmbient, 500, 50)})
+
+	assert.Empty(t, violations)
+	require.Len(t, usage, 2)
+	assert.Nil(t, usage[1].MaxWeight)
+}
+
+func TestCheckRouteWithShipmentCountsShipmentOnce(t *testing.T) {
+	onRoute := shipmentLoad(deliverytypes.TemperatureClassAmbient, 60, 1)
+	repo := &fakeCapacityRepo{
+		vehicle:    &deliverytypes.VehicleCapacity{VehicleID: uuid.New(), VehicleName: "Van 1", MaxWeight: floatPtr(100)},
+		routeLoads: []deliverytypes.ShipmentLoad{onRoute},
+		extraLoads: []deliverytypes.ShipmentLoad{onRoute},
+	}
+	svc := NewDeliveryCapacityService(repo)
+
+	violations, err := svc.CheckRouteWithShipment(context.Background(), uuid.New(), uuid.New(), *onRoute.ShipmentID)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	repo.extraLoads = []deliverytypes.ShipmentLoad{shipmentLoad(deliverytypes.TemperatureClassAmbient, 60, 1)}
+	violations, err = svc.CheckRouteWithShipment(context.Background(), uuid.New(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, []deliverytypes.CapacityViolationCode{deliverytypes.CapacityViolationVehicleWeight}, violationCodes(violations))
+}
+
+func TestCheckRouteWithoutVehicle(t *testing.T) {
+	repo := &fakeCapacityRepo{extraLoads: []deliverytypes.ShipmentLoad{shipmentLoad(deliverytypes.TemperatureClassFrozen, 1, 1)}}
+	svc := NewDeliveryCapacityService(repo)
+
+	violations, err := svc.CheckRouteWithPicking(context.Background(), uuid.New(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, violations, "routes without a vehicle are not checked")
+}
+
+func TestSetVehicleCompartmentsValidates(t *testing.T) {
+	vehicleID := uuid.New()
+	repo := &fakeCapacityRepo{vehicle: &deliverytypes.VehicleCapacity{VehicleID: vehicleID}}
+	svc := NewDeliveryCapacityService(repo)
+	ctx := context.Background()
+
+	compartments, err := svc.SetVehicleCompartments(ctx, uuid.New(), vehicleID, []deliverytypes.VehicleCompartment{
+		{Name: " Dry "},
+		{Name: "Chiller", TemperatureClass: deliverytypes.TemperatureClassChilled, MaxWeight: floatPtr(200)},
+	})
+	require.NoError(t, err)
+	require.Len(t, compartments, 2)
+	assert.Equal(t, "Dry", compartments[0].Name)
+	assert.Equal(t, deliverytypes.TemperatureClassAmbient, compartments[0].TemperatureClass)
+
+	_, err = svc.SetVehicleCompartments(ctx, uuid.New(), vehicleID, []deliverytypes.VehicleCompartment{{Name: "Dry"}, {Name: "dry"}})
+	assert.ErrorIs(t, err, ErrInvalidCompartment)
+	_, err = svc.SetVehicleCompartments(ctx, uuid.New(), vehicleID, []deliverytypes.VehicleCompartment{{Name: "Hot", TemperatureClass: "heated"}})
+	assert.ErrorIs(t, err, ErrInvalidCompartment)
+	_, err = svc.SetVehicleCompartments(ctx, uuid.New(), vehicleID, []deliverytypes.VehicleCompartment{{Name: "Dry", MaxVolume: floatPtr(-1)}})
+	assert.ErrorIs(t, err, ErrInvalidCompartment)
+
+	compartments, err = svc.SetVehicleCompartments(ctx, uuid.New(), uuid.New(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, compartments, "unknown vehicle")
+}
+
+type fakeCapacityChecker struct {
+	violations []deliverytypes.CapacityViolation
+}
+
+func (f fakeCapacityChecker) CheckRouteWithShipment(ctx context.Context, orgID, routeID, shipmentID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	return f.violations, nil
+}
+
+func (f fakeCapacityChecker) CheckRouteWithPicking(ctx context.Context, orgID, routeID, pickingID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	return f.violations, nil
+}
+
+func (f fakeCapacityChecker) CheckRouteWithVehicle(ctx context.Context, orgID, routeID, vehicleID uuid.UUID) ([]deliverytypes.CapacityViolation, error) {
+	return f.violations, nil
+}
+
+func TestCheckCapacityOverride(t *testing.T) {
+	checker := fakeCapacityChecker{violations: []deliverytypes.CapacityViolation{{Code: deliverytypes.CapacityViolationVehicleVolume, Message: "too big"}}}
+	svc := &DeliveryTrackingService{}
+	svc.SetCapacityChecker(checker)
+	check := func() ([]deliverytypes.CapacityViolation, error) {
+		return checker.CheckRouteWithShipment(context.Background(), uuid.Nil, uuid.Nil, uuid.Nil)
+	}
+
+	err := svc.checkCapacity(map[string]interface{}{}, check)
+	var capacityErr *deliverytypes.CapacityError
+	require.True(t, errors.As(err, &capacityErr))
+	assert.Len(t, capacityErr.Violations, 1)
+	assert.Contains(t, err.Error(), "too big")
+
+	metadata := map[string]interface{}{deliverytypes.CapacityOverrideKey: true}
+	require.NoError(t, svc.checkCapacity(metadata, check))
+	assert.Equal(t, checker.violations, metadata[deliverytypes.CapacityViolationsKey], "overridden violations are recorded")
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxConsolidationWindowHours caps how far apart consolidated shipments may be planned
+	MaxConsolidationWindowHours = 14 * 24
+	// ConsolidationPollInterval is how often automatic consolidation runs
+	ConsolidationPollInterval = 15 * time.Minute
+	// consolidationHistoryLimit is the number of applied consolidations listed
+	consolidationHistoryLimit = 100
+)
+
+var (
+	// ErrInvalidConsolidation is returned for settings or consolidations that fail validation
+	ErrInvalidConsolidation = errors.New("invalid consolidation")
+	// ErrConsolidationConflict is returned when shipments changed while being consolidated
+	ErrConsolidationConflict = deliveryrepository.ErrConsolidationConflict
+)
+
+// DefaultConsolidationSettings applies until an organization saves its own
+func DefaultConsolidationSettings() deliverytypes.ConsolidationSettings {
+	return deliverytypes.ConsolidationSettings{
+		WindowHours:      24,
+		MatchZones:       true,
+		ZonePrefixLength: 3,
+		TripCost:         30,
+		StopCost:         5,
+		ShipmentCost:     3,
+	}
+}
+
+type DeliveryConsolidationService struct {
+	repo deliveryrepository.DeliveryConsolidationRepository
+	now  func() time.Time
+}
+
+func NewDeliveryConsolidationService(repo deliveryrepository.DeliveryConsolidationRepository) *DeliveryConsolidationService {
+	return &DeliveryConsolidationService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// GetSettings returns the organization's consolidation settings
+func (s *DeliveryConsolidationService) GetSettings(ctx context.Context, orgID uuid.UUID) (*deliverytypes.ConsolidationSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		defaults := DefaultConsolidationSettings()
+		return &defaults, nil
+	}
+	return settings, nil
+}
+
+// UpdateSettings validates and saves the organization's consolidation settings
+func (s *DeliveryConsolidationService) UpdateSettings(ctx context.Context, orgID uuid.UUID, settings deliverytypes.ConsolidationSettings) (*deliverytypes.ConsolidationSettings, error) {
+	if settings.WindowHours <= 0 || settings.WindowHours > MaxConsolidationWindowHours {
+		return nil, fmt.Errorf("%w: window_hours must be between 1 and %d", ErrInvalidConsolidation, MaxConsolidationWindowHours)
+	}
+	if settings.ZonePrefixLength == 0 {
+		settings.ZonePrefixLength = DefaultConsolidationSettings().ZonePrefixLength
+	}
+	if settings.ZonePrefixLength < 1 || settings.ZonePrefixLength > 10 {
+		return nil, fmt.Errorf("%w: zone_prefix_length must be between 1 and 10", ErrInvalidConsolidation)
+	}
+	if settings.TripCost < 0 || settings.StopCost < 0 || settings.ShipmentCost < 0 {
+		return nil, fmt.Errorf("%w: costs cannot be negative", ErrInvalidConsolidation)
+	}
+
+	if err := s.repo.SaveSettings(ctx, orgID, settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Suggest returns the consolidations worth making now, largest savings first
+func (s *DeliveryConsolidationService) Suggest(ctx context.Context, orgID uuid.UUID) ([]deliverytypes.ConsolidationSuggestion, error) {
+	settings, err := s.GetSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.repo.FindCandidates(ctx, orgID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return suggestConsolidations(candidates, *settings), nil
+}
+
+// Apply consolidates the shipments of a suggestion. The shipments are
+// checked again, so a suggestion that went stale is rejected.
+func (s *DeliveryConsolidationService) Apply(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, req deliverytypes.ConsolidationRequest) (*deliverytypes.Consolidation, error) {
+	settings, err := s.GetSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, orgID, userID, req, *settings, false)
+}
+
+func (s *DeliveryConsolidationService) apply(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, req deliverytypes.ConsolidationRequest, settings deliverytypes.ConsolidationSettings, automatic bool) (*deliverytypes.Consolidation, error) {
+	if !req.Match.IsValid() {
+		return nil, fmt.Errorf("%w: unknown match %q", ErrInvalidConsolidation, req.Match)
+	}
+	ids := make([]uuid.UUID, 0, len(req.ShipmentIDs))
+	for _, id := range req.ShipmentIDs {
+		if !containsUUID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("%w: at least two shipments are needed", ErrInvalidConsolidation)
+	}
+
+	candidates, err := s.repo.FindCandidates(ctx, orgID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) != len(ids) {
+		return nil, fmt.Errorf("%w: some shipments are not pending outbound shipments or were consolidated already", ErrInvalidConsolidation)
+	}
+
+	key := consolidationKey(req.Match, settings)
+	first := key(candidates[0])
+	for _, c := range candidates {
+		if first == "" || key(c) != first {
+			return nil, fmt.Errorf("%w: the shipments do not share a %s", ErrInvalidConsolidation, req.Match)
+		}
+	}
+	window := time.Duration(settings.WindowHours) * time.Hour
+	if candidates[len(candidates)-1].PlannedAt.Sub(candidates[0].PlannedAt) > window {
+		return nil, fmt.Errorf("%w: the shipments are planned more than %d hours apart", ErrInvalidConsolidation, settings.WindowHours)
+	}
+
+	suggestion := buildSuggestion(req.Match, candidates, settings)
+	if !suggestion.Applicable {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConsolidation, suggestion.Reason)
+	}
+
+	var routeID *uuid.UUID
+	for _, c := range candidates {
+		if c.ShipmentID == suggestion.PrimaryShipmentID {
+			routeID = c.RouteID
+		}
+	}
+
+	consolidation := deliverytypes.Consolidation{
+		ID:                uuid.New(),
+		OrganizationID:    orgID,
+		Match:             req.Match,
+		PrimaryShipmentID: suggestion.PrimaryShipmentID,
+		ShipmentIDs:       suggestion.ShipmentIDs,
+		RouteID:           routeID,
+		Savings:           suggestion.Savings,
+		Automatic:         automatic,
+		AppliedBy:         userID,
+		AppliedAt:         s.now(),
+	}
+	if err := s.repo.ApplyConsolidation(ctx, consolidation, candidates); err != nil {
+		return nil, err
+	}
+	return &consolidation, nil
+}
+
+// AutoConsolidate applies the organization's same-address suggestions and
+// returns how many were applied. Suggestions that went stale are skipped.
+func (s *DeliveryConsolidationService) AutoConsolidate(ctx context.Context, orgID uuid.UUID) (int, error) {
+	settings, err := s.GetSettings(ctx, orgID)
+	if err != nil {
+		return 0, err
+	}
+	if !settings.AutoApply {
+		return 0, nil
+	}
+
+	candidates, err := s.repo.FindCandidates(ctx, orgID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, suggestion := range suggestConsolidations(candidates, *settings) {
+		if suggestion.Match != deliverytypes.ConsolidationMatchAddress || !suggestion.Applicable {
+			continue
+		}
+		req := deliverytypes.ConsolidationRequest{Match: suggestion.Match, ShipmentIDs: suggestion.ShipmentIDs}
+		_, err := s.apply(ctx, orgID, nil, req, *settings, true)
+		if errors.Is(err, ErrConsolidationConflict) || errors.Is(err, ErrInvalidConsolidation) {
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// AutoConsolidateAll runs automatic consolidation for every organization
+// that enabled it
+func (s *DeliveryConsolidationService) AutoConsolidateAll(ctx context.Context) (int, error) {
+	orgIDs, err := s.repo.ListAutoApplyOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var errs []error
+	for _, orgID := range orgIDs {
+		applied, err := s.AutoConsolidate(ctx, orgID)
+		total += applied
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organization %s: %w", orgID, err))
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// ListConsolidations returns the latest applied consolidations
+func (s *DeliveryConsolidationService) ListConsolidations(ctx context.Context, orgID uuid.UUID) ([]deliverytypes.Consolidation, error) {
+	return s.repo.ListConsolidations(ctx, orgID, consolidationHistoryLimit)
+}
+
+// suggestConsolidations groups candidates planned within the window of each
+// other by address and, when enabled, by zone. Zone groups are only
+// suggested when they span several addresses and save a trip.
+func suggestConsolidations(candidates []deliverytypes.ConsolidationCandidate, settings deliverytypes.ConsolidationSettings) []deliverytypes.ConsolidationSuggestion {
+	sorted := append([]deliverytypes.ConsolidationCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].PlannedAt.Before(sorted[j].PlannedAt) })
+	window := time.Duration(settings.WindowHours) * time.Hour
+
+	suggestions := []deliverytypes.ConsolidationSuggestion{}
+	for _, match := range []deliverytypes.ConsolidationMatch{deliverytypes.ConsolidationMatchAddress, deliverytypes.ConsolidationMatchZone} {
+		if match == deliverytypes.ConsolidationMatchZone && !settings.MatchZones {
+			continue
+		}
+		for _, cluster := range clusterCandidates(sorted, consolidationKey(match, settings), window) {
+			if match == deliverytypes.ConsolidationMatchZone && distinctAddresses(cluster) < 2 {
+				continue
+			}
+			suggestion := buildSuggestion(match, cluster, settings)
+			if match == deliverytypes.ConsolidationMatchZone && suggestion.Savings.TripsSaved == 0 {
+				continue
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Savings.Total > suggestions[j].Savings.Total })
+	return suggestions
+}
+
+// clusterCandidates groups time-sorted candidates by key, splitting a group
+// where a shipment is planned more than window after the first of its cluster
+func clusterCandidates(sorted []deliverytypes.ConsolidationCandidate, key func(deliverytypes.ConsolidationCandidate) string, window time.Duration) [][]deliverytypes.ConsolidationCandidate {
+	var keys []string
+	groups := make(map[string][]deliverytypes.ConsolidationCandidate)
+	for _, c := range sorted {
+		k := key(c)
+		if k == "" {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+
+	var clusters [][]deliverytypes.ConsolidationCandidate
+	for _, k := range keys {
+		var current []deliverytypes.ConsolidationCandidate
+		for _, c := range groups[k] {
+			if len(current) > 0 && c.PlannedAt.Sub(current[0].PlannedAt) > window {
+				if len(current) > 1 {
+					clusters = append(clusters, current)
+				}
+				current = nil
+			}
+			current = append(current, c)
+		}
+		if len(current) > 1 {
+			clusters = append(clusters, current)
+		}
+	}
+	return clusters
+}
+
+// buildSuggestion picks the primary shipment, the earliest one already on a
+// route or else the earliest one, and projects the savings
+func buildSuggestion(match deliverytypes.ConsolidationMatch, members []deliverytypes.ConsolidationCandidate, settings deliverytypes.ConsolidationSettings) deliverytypes.ConsolidationSuggestion {
+	suggestion := deliverytypes.ConsolidationSuggestion{
+		Match:       match,
+		Shipments:   members,
+		WindowStart: members[0].PlannedAt,
+		WindowEnd:   members[0].PlannedAt,
+		Applicable:  true,
+	}
+
+	primary := members[0]
+	routed := false
+	trips := make(map[string]bool)
+	for _, c := range members {
+		suggestion.ShipmentIDs = append(suggestion.ShipmentIDs, c.ShipmentID)
+		suggestion.Weight += c.Weight
+		suggestion.Volume += c.Volume
+		if c.PlannedAt.Before(suggestion.WindowStart) {
+			suggestion.WindowStart = c.PlannedAt
+		}
+		if c.PlannedAt.After(suggestion.WindowEnd) {
+			suggestion.WindowEnd = c.PlannedAt
+		}
+		if c.RouteID != nil {
+			trips[c.RouteID.String()] = true
+			if !routed {
+				primary, routed = c, true
+			}
+		} else {
+			// An unrouted shipment would be delivered on a run of its own
+			trips[c.ShipmentID.String()] = true
+		}
+	}
+	suggestion.PrimaryShipmentID = primary.ShipmentID
+
+	others := len(members) - 1
+	suggestion.Savings.TripsSaved = len(trips) - 1
+	switch match {
+	case deliverytypes.ConsolidationMatchAddress:
+		suggestion.Destination = formatCandidateAddress(primary)
+		suggestion.Savings.StopsSaved = others
+		suggestion.Savings.ShipmentsSaved = others
+	case deliverytypes.ConsolidationMatchZone:
+		suggestion.Destination = "Zone " + zonePrefix(primary.Zip, settings.ZonePrefixLength)
+		if !routed {
+			suggestion.Applicable = false
+			suggestion.Reason = "none of the shipments is on a route yet; assign one to a route first"
+		}
+	}
+
+	total := float64(suggestion.Savings.TripsSaved)*settings.TripCost +
+		float64(suggestion.Savings.StopsSaved)*settings.StopCost +
+		float64(suggestion.Savings.ShipmentsSaved)*settings.ShipmentCost
+	suggestion.Savings.Total = math.Round(total*100) / 100
+
+	return suggestion
+}
+
+// consolidationKey returns what candidates must share to be consolidated by match
+func consolidationKey(match deliverytypes.ConsolidationMatch, settings deliverytypes.ConsolidationSettings) func(deliverytypes.ConsolidationCandidate) string {
+	if match == deliverytypes.ConsolidationMatchZone {
+		return func(c deliverytypes.ConsolidationCandidate) string {
+			prefix := zonePrefix(c.Zip, settings.ZonePrefixLength)
+			if prefix == "" {
+				return ""
+			}
+			return countryKey(c) + "|" + prefix
+		}
+	}
+	return addressKey
+}
+
+// addressKey identifies a destination by its normalized street and postal
+// code, or by the partner when the address is unknown
+func addressKey(c deliverytypes.ConsolidationCandidate) string {
+	street := normalizeAddressPart(c.Street)
+	if street != "" {
+		return countryKey(c) + "|" + normalizeAddressPart(c.Zip) + "|" + street
+	}
+	if c.PartnerID != nil {
+		return "partner:" + c.PartnerID.String()
+	}
+	return ""
+}
+
+func countryKey(c deliverytypes.ConsolidationCandidate) string {
+	if c.CountryID == nil {
+		return ""
+	}
+	return c.CountryID.String()
+}
+
+// zonePrefix returns the leading characters of a normalized postal code
+func zonePrefix(zip string, length int) string {
+	zip = strings.ToUpper(normalizeAddressPart(zip))
+	if len(zip) > length {
+		zip = zip[:length]
+	}
+	return zip
+}
+
+// normalizeAddressPart lowercases and keeps letters and digits only, so
+// "12 Main St." and "12 main st" match
+func normalizeAddressPart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func distinctAddresses(members []deliverytypes.ConsolidationCandidate) int {
+	addresses := make(map[string]bool)
+	for _, c := range members {
+		key := addressKey(c)
+		if key == "" {
+			key = c.ShipmentID.String()
+		}
+		addresses[key] = true
+	}
+	return len(addresses)
+}
+
+func formatCandidateAddress(c deliverytypes.ConsolidationCandidate) string {
+	var parts []string
+	for _, part := range []string{c.Street, strings.TrimSpace(c.Zip + " " + c.City)} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 && c.PartnerID != nil {
+		return "Partner " + c.PartnerID.String()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsolidationRepo struct {
+	settings   *deliverytypes.ConsolidationSettings
+	candidates []deliverytypes.ConsolidationCandidate
+	applied    []deliverytypes.Consolidation
+	applyErr   error
+}
+
+func (f *fakeConsolidationRepo) GetSettings(ctx context.Context, orgID uuid.UUID) (*deliverytypes.ConsolidationSettings, error) {
+	return f.settings, nil
+}
+
+func (f *fakeConsolidationRepo) SaveSettings(ctx context.Context, orgID uuid.UUID, settings deliverytypes.ConsolidationSettings) error {
+	f.settings = &settings
+	return nil
+}
+
+func (f *fakeConsolidationRepo) ListAutoApplyOrganizations(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeConsolidationRepo) FindCandidates(ctx context.Context, orgID uuid.UUID, shipmentIDs []uuid.UUID) ([]deliverytypes.ConsolidationCandidate, error) {
+	var found []deliverytypes.ConsolidationCandidate
+	for _, c := range f.candidates {
+		if len(shipmentIDs) == 0 || containsUUID(shipmentIDs, c.ShipmentID) {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeConsolidationRepo) ApplyConsolidation(ctx context.Context, consolidation deliverytypes.Consolidation, candidates []deliverytypes.ConsolidationCandidate) error {
+	if f.applyErr != nil {
+		return f.applyErr
+	}
+	f.applied = append(f.applied, consolidation)
+	// Merged shipments stop being candidates
+	remaining := f.candidates[:0]
+	for _, c := range f.candidates {
+		if !containsUUID(consolidation.ShipmentIDs, c.ShipmentID) {
+			remaining = append(remaining, c)
+		}
+	}
+	f.candidates = remaining
+	return nil
+}
+
+func (f *fakeConsolidationRepo) ListConsolidations(ctx context.Context, orgID uuid.UUID, limit int) ([]deliverytypes.Consolidation, error) {
+	return f.applied, nil
+}
+
+var consolidationBase = time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+
+func consolidationCandidate(street, zip string, plannedIn time.Duration, routeID *uuid.UUID) deliverytypes.ConsolidationCandidate {
+	return deliverytypes.ConsolidationCandidate{
+		ShipmentID: uuid.New(),
+		RouteID:    routeID,
+		Street:     street,
+		Zip:        zip,
+		City:       "Springfield",
+		PlannedAt:  consolidationBase.Add(plannedIn),
+		Weight:     10,
+		Volume:     0.5,
+	}
+}
+
+func TestSuggestSameAddress(t *testing.T) {
+	routeID := uuid.New()
+	first := consolidationCandidate("12 Main St.", "H2X 1Y4", 0, nil)
+	second := consolidationCandidate("12 main st", "h2x1y4", 3*time.Hour, &routeID)
+	later := consolidationCandidate("12 Main St", "H2X 1Y4", 40*time.Hour, nil)
+	repo := &fakeConsolidationRepo{candidates: []deliverytypes.ConsolidationCandidate{later, second, first}}
+	svc := NewDeliveryConsolidationService(repo)
+
+	suggestions, err := svc.Suggest(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1, "the later shipment is outside the window and zone matching needs several addresses")
+
+	s := suggestions[0]
+	assert.Equal(t, deliverytypes.ConsolidationMatchAddress, s.Match)
+	assert.Equal(t, []uuid.UUID{first.ShipmentID, second.ShipmentID}, s.ShipmentIDs)
+	assert.Equal(t, second.ShipmentID, s.PrimaryShipmentID, "the routed shipment is kept")
+	assert.Equal(t, "12 main st, h2x1y4 Springfield", s.Destination)
+	assert.Equal(t, 20.0, s.Weight)
+	assert.True(t, s.Applicable)
+	assert.Equal(t, deliverytypes.ConsolidationSavings{TripsSaved: 1, StopsSaved: 1, ShipmentsSaved: 1, Total: 38}, s.Savings)
+}
+
+func TestSuggestZone(t *testing.T) {
+	routeID := uuid.New()
+	routed := consolidationCandidate("1 Oak Ave", "H2X 1A1", time.Hour, &routeID)
+	unrouted := consolidationCandidate("99 Pine Rd", "H2X 9Z9", 2*time.Hour, nil)
+	elsewhere := consolidationCandidate("5 Elm St", "K1A 0B1", 2*time.Hour, nil)
+	repo := &fakeConsolidationRepo{candidates: []deliverytypes.ConsolidationCandidate{routed, unrouted, elsewhere}}
+	svc := NewDeliveryConsolidationService(repo)
+
+	suggestions, err := svc.Suggest(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	s := suggestions[0]
+	assert.Equal(t, deliverytypes.ConsolidationMatchZone, s.Match)
+	assert.Equal(t, "Zone H2X", s.Destination)
+	assert.Equal(t, routed.ShipmentID, s.PrimaryShipmentID)
+	assert.Equal(t, deliverytypes.ConsolidationSavings{TripsSaved: 1, Total: 30}, s.Savings)
+
+	settings := DefaultConsolidationSettings()
+	settings.MatchZones = false
+	repo.settings = &settings
+	suggestions, err = svc.Suggest(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+}
+
+func TestSuggestZoneNeedsRoute(t *testing.T) {
+	repo := &fakeConsolidationRepo{candidates: []deliverytypes.ConsolidationCandidate{
+		consolidationCandidate("1 Oak Ave", "H2X 1A1", 0, nil),
+		consolidationCandidate("99 Pine Rd", "H2X 9Z9", 0, nil),
+	}}
+	svc := NewDeliveryConsolidationService(repo)
+
+	suggestions, err := svc.Suggest(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.False(t, suggestions[0].Applicable)
+	assert.NotEmpty(t, suggestions[0].Reason)
+
+	_, err = svc.Apply(context.Background(), uuid.New(), nil, deliverytypes.ConsolidationRequest{
+		Match:       deliverytypes.ConsolidationMatchZone,
+		ShipmentIDs: suggestions[0].ShipmentIDs,
+	})
+	assert.ErrorIs(t, err, ErrInvalidConsolidation)
+	assert.Empty(t, repo.applied)
+}
+
+func TestApplyValidatesShipments(t *testing.T) {
+	a := consolidationCandidate("12 Main St", "H2X 1Y4", 0, nil)
+	b := consolidationCandidate("12 Main St", "H2X 1Y4", time.Hour, nil)
+	other := consolidationCandidate("7 Side St", "H2X 1Y4", time.Hour, nil)
+	late := consolidationCandidate("12 Main St", "H2X 1Y4", 48*time.Hour, nil)
+	repo := &fakeConsolidationRepo{candidates: []deliverytypes.ConsolidationCandidate{a, b, other, late}}
+	svc := NewDeliveryConsolidationService(repo)
+	ctx := context.Background()
+	address := deliverytypes.ConsolidationMatchAddress
+
+	for name, req := range map[string]deliverytypes.ConsolidationRequest{
+		"unknown match":     {Match: "street", ShipmentIDs: []uuid.UUID{a.ShipmentID, b.ShipmentID}},
+		"single shipment":   {Match: address, ShipmentIDs: []uuid.UUID{a.ShipmentID, a.ShipmentID}},
+		"not pending":       {Match: address, ShipmentIDs: []uuid.UUID{a.ShipmentID, uuid.New()}},
+		"other address":     {Match: address, ShipmentIDs: []uuid.UUID{a.ShipmentID, other.ShipmentID}},
+		"outside of window": {Match: address, ShipmentIDs: []uuid.UUID{a.ShipmentID, late.ShipmentID}},
+	} {
+		_, err := svc.Apply(ctx, uuid.New(), nil, req)
+		assert.ErrorIs(t, err, ErrInvalidConsolidation, name)
+	}
+	assert.Empty(t, repo.applied)
+
+	userID := uuid.New()
+	consolidation, err := svc.Apply(ctx, uuid.New(), &userID, deliverytypes.ConsolidationRequest{Match: address, ShipmentIDs: []uuid.UUID{b.ShipmentID, a.ShipmentID}})
+	require.NoError(t, err)
+	assert.Equal(t, a.ShipmentID, consolidation.PrimaryShipmentID)
+	assert.ElementsMatch(t, []uuid.UUID{a.ShipmentID, b.ShipmentID}, consolidation.ShipmentIDs)
+	assert.False(t, consolidation.Automatic)
+	assert.Equal(t, &userID, consolidation.AppliedBy)
+
+	repo.candidates = []deliverytypes.ConsolidationCandidate{a, b}
+	repo.applyErr = ErrConsolidationConflict
+	_, err = svc.Apply(ctx, uuid.New(), &userID, deliverytypes.ConsolidationRequest{Match: address, ShipmentIDs: []uuid.UUID{a.ShipmentID, b.ShipmentID}})
+	assert.ErrorIs(t, err, ErrConsolidationConflict)
+}
+
+func TestAutoConsolidateAppliesAddressMatchesOnly(t *testing.T) {
+	routeID := uuid.New()
+	repo := &fakeConsolidationRepo{candidates: []deliverytypes.ConsolidationCandidate{
+		consolidationCandidate("12 Main St", "H2X 1Y4", 0, nil),
+		consolidationCandidate("12 Main St", "H2X 1Y4", time.Hour, nil),
+		consolidationCandidate("1 Oak Ave", "H2X 1A1", time.Hour, &routeID),
+	}}
+	svc := NewDeliveryConsolidationService(repo)
+	ctx := context.Background()
+
+	applied, err := svc.AutoConsolidate(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Zero(t, applied, "automatic consolidation is off by default")
+
+	settings := DefaultConsolidationSettings()
+	settings.AutoApply = true
+	repo.settings = &settings
+	applied, err = svc.AutoConsolidate(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	require.Len(t, repo.applied, 1)
+	assert.Equal(t, deliverytypes.ConsolidationMatchAddress, repo.applied[0].Match)
+	assert.True(t, repo.applied[0].Automatic)
+	assert.Nil(t, repo.applied[0].AppliedBy)
+}
+
+func TestUpdateConsolidationSettings(t *testing.T) {
+	repo := &fakeConsolidationRepo{}
+	svc := NewDeliveryConsolidationService(repo)
+	ctx := context.Background()
+
+	settings, err := svc.GetSettings(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConsolidationSettings(), *settings)
+
+	_, err = svc.UpdateSettings(ctx, uuid.New(), deliverytypes.ConsolidationSettings{WindowHours: 0})
+	assert.ErrorIs(t, err, ErrInvalidConsolidation)
+	_, err = svc.UpdateSettings(ctx, uuid.New(), deliverytypes.ConsolidationSettings{WindowHours: 12, TripCost: -1})
+	assert.ErrorIs(t, err, ErrInvalidConsolidation)
+
+	updated, err := svc.UpdateSettings(ctx, uuid.New(), deliverytypes.ConsolidationSettings{WindowHours: 12, AutoApply: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated.ZonePrefixLength, "zone prefix defaults when omitted")
+	assert.Equal(t, updated, repo.settings)
+}
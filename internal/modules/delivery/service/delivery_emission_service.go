@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultCarrierKgPerTonneKm is the CO2e of average road freight per tonne-kilometre
+	DefaultCarrierKgPerTonneKm = 0.105
+	// MaxEmissionReportDays caps the date range of a sustainability report
+	MaxEmissionReportDays = 366
+)
+
+// defaultVehicleKgPerKm is the CO2e of an average vehicle of each type per kilometre
+var defaultVehicleKgPerKm = map[string]float64{
+	"truck": 0.9,
+	"van":   0.25,
+	"car":   0.17,
+	"bike":  0,
+	"drone": 0.01,
+	"other": 0.5,
+}
+
+// ErrInvalidEmissionReport is returned for emission factors or report filters that fail validation
+var ErrInvalidEmissionReport = errors.New("invalid emission report")
+
+// DefaultEmissionFactors applies to factors an organization has not set
+func DefaultEmissionFactors() deliverytypes.EmissionFactors {
+	vehicles := make(map[string]float64, len(defaultVehicleKgPerKm))
+	for vehicleType, factor := range defaultVehicleKgPerKm {
+		vehicles[vehicleType] = factor
+	}
+	return deliverytypes.EmissionFactors{
+		VehicleKgPerKm:             vehicles,
+		CarrierKgPerTonneKm:        map[string]float64{},
+		DefaultCarrierKgPerTonneKm: DefaultCarrierKgPerTonneKm,
+	}
+}
+
+type DeliveryEmissionService struct {
+	repo deliveryrepository.DeliveryEmissionRepository
+}
+
+func NewDeliveryEmissionService(repo deliveryrepository.DeliveryEmissionRepository) *DeliveryEmissionService {
+	return &DeliveryEmissionService{
+		repo: repo,
+	}
+}
+
+// GetFactors returns the organization's emission factors merged over the defaults
+func (s *DeliveryEmissionService) GetFactors(ctx context.Context, orgID uuid.UUID) (*deliverytypes.EmissionFactors, error) {
+	saved, err := s.repo.GetFactors(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	factors := DefaultEmissionFactors()
+	if saved == nil {
+		return &factors, nil
+	}
+	for vehicleType, factor := range saved.VehicleKgPerKm {
+		factors.VehicleKgPerKm[vehicleType] = factor
+	}
+	for carrier, factor := range saved.CarrierKgPerTonneKm {
+		factors.CarrierKgPerTonneKm[carrier] = factor
+	}
+	if saved.DefaultCarrierKgPerTonneKm > 0 {
+		factors.DefaultCarrierKgPerTonneKm = saved.DefaultCarrierKgPerTonneKm
+	}
+	return &factors, nil
+}
+
+// UpdateFactors validates and saves the organization's emission factors and
+// returns them merged over the defaults
+func (s *DeliveryEmissionService) UpdateFactors(ctx context.Context, orgID uuid.UUID, factors deliverytypes.EmissionFactors) (*deliverytypes.EmissionFactors, error) {
+	for vehicleType, factor := range factors.VehicleKgPerKm {
+		if _, ok := defaultVehicleKgPerKm[vehicleType]; !ok {
+			return nil, fmt.Errorf("%w: unknown vehicle type %q", ErrInvalidEmissionReport, vehicleType)
+		}
+		if factor < 0 {
+			return nil, fmt.Errorf("%w: factor of %s cannot be negative", ErrInvalidEmissionReport, vehicleType)
+		}
+	}
+	for carrier, factor := range factors.CarrierKgPerTonneKm {
+		if carrier == "" {
+			return nil, fmt.Errorf("%w: carrier code is required", ErrInvalidEmissionReport)
+		}
+		if factor < 0 {
+			return nil, fmt.Errorf("%w: factor of carrier %s cannot be negative", ErrInvalidEmissionReport, carrier)
+		}
+	}
+	if factors.DefaultCarrierKgPerTonneKm < 0 {
+		return nil, fmt.Errorf("%w: default carrier factor cannot be negative", ErrInvalidEmissionReport)
+	}
+
+	if err := s.repo.SaveFactors(ctx, orgID, factors); err != nil {
+		return nil, err
+	}
+	return s.GetFactors(ctx, orgID)
+}
+
+// GetRouteEmissions estimates the CO2e of a route and shares it between its
+// shipments. It returns nil when the route does not exist.
+func (s *DeliveryEmissionService) GetRouteEmissions(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.RouteEmission, error) {
+	trip, err := s.repo.FindTrip(ctx, orgID, routeID)
+	if err != nil || trip == nil {
+		return nil, err
+	}
+	factors, err := s.GetFactors(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	emission := estimateTrip(*trip, *factors)
+	return &emission, nil
+}
+
+// GetSustainabilityReport totals the CO2e of the routes driven and the
+// carrier shipments sent in the date range, per period and per customer
+func (s *DeliveryEmissionService) GetSustainabilityReport(ctx context.Context, filter deliverytypes.SustainabilityReportFilter) (*deliverytypes.SustainabilityReport, error) {
+	if filter.Period == "" {
+		filter.Period = deliverytypes.EmissionPeriodMonth
+	}
+	if !filter.Period.IsValid() {
+		return nil, fmt.Errorf("%w: unknown period %q", ErrInvalidEmissionReport, filter.Period)
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidEmissionReport)
+	}
+	if filter.To.Sub(filter.From) > MaxEmissionReportDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the date range cannot exceed %d days", ErrInvalidEmissionReport, MaxEmissionReportDays)
+	}
+
+	factors, err := s.GetFactors(ctx, filter.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := s.repo.FindTrips(ctx, filter.OrganizationID, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	carrierShipments, err := s.repo.FindCarrierShipments(ctx, filter.OrganizationID, filter.From, filter.To, filter.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := newEmissionReportBuilder(filter)
+	for _, trip := range trips {
+		builder.addRoute(estimateTrip(trip, *factors))
+	}
+	for _, shipment := range carrierShipments {
+		builder.addShipment(estimateCarrierShipment(shipment, *factors))
+	}
+	return builder.build(), nil
+}
+
+// estimateTrip multiplies the route's distance by its vehicle's factor and
+// shares the result between the shipments by weight, or evenly when their
+// weight is unknown. GPS distance is preferred over the planned one.
+func estimateTrip(trip deliverytypes.EmissionTrip, factors deliverytypes.EmissionFactors) deliverytypes.RouteEmission {
+	emission := deliverytypes.RouteEmission{
+		RouteID:        trip.RouteID,
+		RouteCode:      trip.RouteCode,
+		RouteName:      trip.RouteName,
+		VehicleType:    trip.VehicleType,
+		DistanceSource: deliverytypes.DistanceSourceNone,
+		Estimated:      true,
+		Date:           trip.Date,
+		Shipments:      []deliverytypes.ShipmentEmission{},
+	}
+
+	switch {
+	case trip.GPSDistanceKm > 0:
+		emission.DistanceKm = trip.GPSDistanceKm
+		emission.DistanceSource = deliverytypes.DistanceSourceGPS
+	case trip.PlannedDistanceKm != nil:
+		emission.DistanceKm = *trip.PlannedDistanceKm
+		emission.DistanceSource = deliverytypes.DistanceSourcePlanned
+	default:
+		emission.Estimated = false
+		emission.Reason = "distance unknown: no GPS positions and no distance_km in the route metadata"
+	}
+
+	switch {
+	case trip.VehicleKgPerKm != nil:
+		emission.KgPerKm = *trip.VehicleKgPerKm
+	case trip.VehicleType == "":
+		emission.Estimated = false
+		emission.Reason = "no vehicle assigned to the route"
+	default:
+		factor, ok := factors.VehicleKgPerKm[trip.VehicleType]
+		if !ok {
+			factor = factors.VehicleKgPerKm["other"]
+		}
+		emission.KgPerKm = factor
+	}
+
+	if emission.Estimated {
+		emission.KgCO2e = emission.DistanceKm * emission.KgPerKm
+	}
+
+	var totalWeight float64
+	for _, shipment := range trip.Shipments {
+		totalWeight += shipment.WeightKg
+	}
+	for _, shipment := range trip.Shipments {
+		share := 1 / float64(len(trip.Shipments))
+		if totalWeight > 0 {
+			share = shipment.WeightKg / totalWeight
+		}
+		emission.Shipments = append(emission.Shipments, deliverytypes.ShipmentEmission{
+			ShipmentID:     shipment.ShipmentID,
+			RouteID:        shipment.RouteID,
+			TrackingNumber: shipment.TrackingNumber,
+			CustomerID:     shipment.CustomerID,
+			CustomerName:   shipment.CustomerName,
+			CarrierCode:    shipment.CarrierCode,
+			Method:         deliverytypes.EmissionMethodFleet,
+			WeightKg:       shipment.WeightKg,
+			DistanceKm:     emission.DistanceKm,
+			KgCO2e:         roundKg(emission.KgCO2e * share),
+			Date:           trip.Date,
+			Estimated:      emission.Estimated,
+			Reason:         emission.Reason,
+		})
+	}
+	emission.KgCO2e = roundKg(emission.KgCO2e)
+	return emission
+}
+
+// estimateCarrierShipment multiplies the shipment's weight in tonnes, its
+// distance and the carrier's factor
+func estimateCarrierShipment(shipment deliverytypes.EmissionShipment, factors deliverytypes.EmissionFactors) deliverytypes.ShipmentEmission {
+	emission := deliverytypes.ShipmentEmission{
+		ShipmentID:     shipment.ShipmentID,
+		TrackingNumber: shipment.TrackingNumber,
+		CustomerID:     shipment.CustomerID,
+		CustomerName:   shipment.CustomerName,
+		CarrierCode:    shipment.CarrierCode,
+		Method:         deliverytypes.EmissionMethodCarrier,
+		WeightKg:       shipment.WeightKg,
+		Date:           shipment.Date,
+	}
+
+	switch {
+	case shipment.DistanceKm == nil:
+		emission.Reason = "distance unknown: no distance_km in the shipment metadata"
+		return emission
+	case shipment.WeightKg <= 0:
+		emission.Reason = "weight unknown: the shipped products have no weight"
+		return emission
+	}
+
+	factor, ok := factors.CarrierKgPerTonneKm[shipment.CarrierCode]
+	if !ok {
+		factor = factors.DefaultCarrierKgPerTonneKm
+	}
+	emission.DistanceKm = *shipment.DistanceKm
+	emission.KgCO2e = roundKg(shipment.WeightKg / 1000 * emission.DistanceKm * factor)
+	emission.Estimated = true
+	return emission
+}
+
+// emissionReportBuilder accumulates estimates into a sustainability report
+type emissionReportBuilder struct {
+	filter    deliverytypes.SustainabilityReportFilter
+	report    deliverytypes.SustainabilityReport
+	periods   map[time.Time]*deliverytypes.EmissionPeriodTotal
+	customers map[string]*deliverytypes.EmissionCustomerTotal
+}
+
+func newEmissionReportBuilder(filter deliverytypes.SustainabilityReportFilter) *emissionReportBuilder {
+	return &emissionReportBuilder{
+		filter: filter,
+		report: deliverytypes.SustainabilityReport{
+			From:       filter.From,
+			To:         filter.To,
+			Period:     filter.Period,
+			CustomerID: filter.CustomerID,
+			ByMethod:   map[deliverytypes.EmissionMethod]float64{},
+		},
+		periods:   make(map[time.Time]*deliverytypes.EmissionPeriodTotal),
+		customers: make(map[string]*deliverytypes.EmissionCustomerTotal),
+	}
+}
+
+// addRoute adds a route's shipments. For a customer report only that
+// customer's shipments count, and routes without any are left out.
+func (b *emissionReportBuilder) addRoute(route deliverytypes.RouteEmission) {
+	var shipments []deliverytypes.ShipmentEmission
+	for _, shipment := range route.Shipments {
+		if b.filter.CustomerID == nil || (shipment.CustomerID != nil && *shipment.CustomerID == *b.filter.CustomerID) {
+			shipments = append(shipments, shipment)
+		}
+	}
+	if b.filter.CustomerID != nil && len(shipments) == 0 {
+		return
+	}
+
+	b.report.Routes++
+	b.report.FleetDistanceKm += route.DistanceKm
+	if len(route.Shipments) == 0 {
+		b.report.KgCO2e += route.KgCO2e
+		b.report.ByMethod[deliverytypes.EmissionMethodFleet] += route.KgCO2e
+		b.period(route.Date).KgCO2e += route.KgCO2e
+		return
+	}
+	for _, shipment := range shipments {
+		b.addShipment(shipment)
+	}
+}
+
+func (b *emissionReportBuilder) addShipment(shipment deliverytypes.ShipmentEmission) {
+	b.report.Shipments++
+	if !shipment.Estimated {
+		b.report.UnestimatedShipments++
+	}
+	b.report.KgCO2e += shipment.KgCO2e
+	b.report.ByMethod[shipment.Method] += shipment.KgCO2e
+
+	period := b.period(shipment.Date)
+	period.KgCO2e += shipment.KgCO2e
+	period.Shipments++
+
+	key := ""
+	if shipment.CustomerID != nil {
+		key = shipment.CustomerID.String()
+	}
+	customer, ok := b.customers[key]
+	if !ok {
+		customer = &deliverytypes.EmissionCustomerTotal{CustomerID: shipment.CustomerID, CustomerName: shipment.CustomerName}
+		if shipment.CustomerID == nil {
+			customer.CustomerName = "No customer"
+		}
+		b.customers[key] = customer
+	}
+	customer.KgCO2e += shipment.KgCO2e
+	customer.Shipments++
+	customer.WeightKg += shipment.WeightKg
+}
+
+func (b *emissionReportBuilder) period(at time.Time) *deliverytypes.EmissionPeriodTotal {
+	start := periodStart(at, b.filter.Period)
+	period, ok := b.periods[start]
+	if !ok {
+		period = &deliverytypes.EmissionPeriodTotal{PeriodStart: start}
+		b.periods[start] = period
+	}
+	return period
+}
+
+func (b *emissionReportBuilder) build() *deliverytypes.SustainabilityReport {
+	report := b.report
+	report.KgCO2e = roundKg(report.KgCO2e)
+	report.FleetDistanceKm = roundKg(report.FleetDistanceKm)
+	for method, kg := range report.ByMethod {
+		report.ByMethod[method] = roundKg(kg)
+	}
+
+	report.ByPeriod = make([]deliverytypes.EmissionPeriodTotal, 0, len(b.periods))
+	for _, period := range b.periods {
+		period.KgCO2e = roundKg(period.KgCO2e)
+		report.ByPeriod = append(report.ByPeriod, *period)
+	}
+	sort.Slice(report.ByPeriod, func(i, j int) bool {
+		return report.ByPeriod[i].PeriodStart.Before(report.ByPeriod[j].PeriodStart)
+	})
+
+	report.ByCustomer = make([]deliverytypes.EmissionCustomerTotal, 0, len(b.customers))
+	for _, customer := range b.customers {
+		customer.KgCO2e = roundKg(customer.KgCO2e)
+		customer.WeightKg = roundKg(customer.WeightKg)
+		report.ByCustomer = append(report.ByCustomer, *customer)
+	}
+	sort.Slice(report.ByCustomer, func(i, j int) bool {
+		if report.ByCustomer[i].KgCO2e != report.ByCustomer[j].KgCO2e {
+			return report.ByCustomer[i].KgCO2e > report.ByCustomer[j].KgCO2e
+		}
+		return report.ByCustomer[i].CustomerName < report.ByCustomer[j].CustomerName
+	})
+	return &report
+}
+
+// periodStart returns the start of the day, ISO week or month of at, in UTC
+func periodStart(at time.Time, period deliverytypes.EmissionPeriod) time.Time {
+	at = at.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case deliverytypes.EmissionPeriodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case deliverytypes.EmissionPeriodMonth:
+		return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// roundKg rounds to the gram
+func roundKg(kg float64) float64 {
+	return math.Round(kg*1000) / 1000
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEmissionRepo struct {
+	factors  *deliverytypes.EmissionFactors
+	trips    []deliverytypes.EmissionTrip
+	carriers []deliverytypes.EmissionShipment
+}
+
+func (f *fakeEmissionRepo) GetFactors(ctx context.Context, orgID uuid.UUID) (*deliverytypes.EmissionFactors, error) {
+	return f.factors, nil
+}
+
+func (f *fakeEmissionRepo) SaveFactors(ctx context.Context, orgID uuid.UUID, factors deliverytypes.EmissionFactors) error {
+	f.factors = &factors
+	return nil
+}
+
+func (f *fakeEmissionRepo) FindTrip(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.EmissionTrip, error) {
+	for _, trip := range f.trips {
+		if trip.RouteID == routeID {
+			return &trip, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeEmissionRepo) FindTrips(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]deliverytypes.EmissionTrip, error) {
+	return f.trips, nil
+}
+
+func (f *fakeEmissionRepo) FindCarrierShipments(ctx context.Context, orgID uuid.UUID, from, to time.Time, customerID *uuid.UUID) ([]deliverytypes.EmissionShipment, error) {
+	var shipments []deliverytypes.EmissionShipment
+	for _, s := range f.carriers {
+		if customerID == nil || (s.CustomerID != nil && *s.CustomerID == *customerID) {
+			shipments = append(shipments, s)
+		}
+	}
+	return shipments, nil
+}
+
+func emissionShipment(customerID *uuid.UUID, weight float64) deliverytypes.EmissionShipment {
+	return deliverytypes.EmissionShipment{ShipmentID: uuid.New(), CustomerID: customerID, CustomerName: "Acme", WeightKg: weight}
+}
+
+func TestRouteEmissionsSharedByWeight(t *testing.T) {
+	routeID := uuid.New()
+	heavy := emissionShipment(nil, 300)
+	light := emissionShipment(nil, 100)
+	repo := &fakeEmissionRepo{trips: []deliverytypes.EmissionTrip{{
+		RouteID:           routeID,
+		RouteName:         "North loop",
+		VehicleType:       "van",
+		GPSDistanceKm:     120,
+		PlannedDistanceKm: floatPtr(100),
+		Shipments:         []deliverytypes.EmissionShipment{heavy, light},
+	}}}
+	svc := NewDeliveryEmissionService(repo)
+
+	emission, err := svc.GetRouteEmissions(context.Background(), uuid.New(), routeID)
+	require.NoError(t, err)
+	require.NotNil(t, emission)
+	assert.Equal(t, deliverytypes.DistanceSourceGPS, emission.DistanceSource)
+	assert.Equal(t, 0.25, emission.KgPerKm)
+	assert.Equal(t, 30.0, emission.KgCO2e)
+	require.Len(t, emission.Shipments, 2)
+	assert.Equal(t, 22.5, emission.Shipments[0].KgCO2e)
+	assert.Equal(t, 7.5, emission.Shipments[1].KgCO2e)
+	assert.True(t, emission.Shipments[0].Estimated)
+
+	missing, err := svc.GetRouteEmissions(context.Background(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestEstimateTripFallbacks(t *testing.T) {
+	factors := DefaultEmissionFactors()
+	factors.VehicleKgPerKm["truck"] = 1.2
+
+	planned := estimateTrip(deliverytypes.EmissionTrip{VehicleType: "truck", PlannedDistanceKm: floatPtr(50)}, factors)
+	assert.Equal(t, deliverytypes.DistanceSourcePlanned, planned.DistanceSource)
+	assert.Equal(t, 60.0, planned.KgCO2e)
+
+	override := estimateTrip(deliverytypes.EmissionTrip{VehicleType: "truck", VehicleKgPerKm: floatPtr(0.3), GPSDistanceKm: 10}, factors)
+	assert.Equal(t, 3.0, override.KgCO2e, "the vehicle's own factor wins over its type")
+
+	even := estimateTrip(deliverytypes.EmissionTrip{
+		VehicleType:   "car",
+		GPSDistanceKm: 100,
+		Shipments:     []deliverytypes.EmissionShipment{emissionShipment(nil, 0), emissionShipment(nil, 0)},
+	}, factors)
+	assert.Equal(t, 8.5, even.Shipments[0].KgCO2e, "unknown weights share evenly")
+
+	noDistance := estimateTrip(deliverytypes.EmissionTrip{VehicleType: "van"}, factors)
+	assert.False(t, noDistance.Estimated)
+	assert.Equal(t, deliverytypes.DistanceSourceNone, noDistance.DistanceSource)
+	assert.Zero(t, noDistance.KgCO2e)
+
+	noVehicle := estimateTrip(deliverytypes.EmissionTrip{GPSDistanceKm: 10}, factors)
+	assert.False(t, noVehicle.Estimated)
+	assert.NotEmpty(t, noVehicle.Reason)
+}
+
+func TestEstimateCarrierShipment(t *testing.T) {
+	factors := DefaultEmissionFactors()
+	factors.CarrierKgPerTonneKm["DHL"] = 0.08
+
+	shipment := emissionShipment(nil, 500)
+	shipment.DistanceKm = floatPtr(400)
+	shipment.CarrierCode = "DHL"
+	assert.Equal(t, 16.0, estimateCarrierShipment(shipment, factors).KgCO2e)
+
+	shipment.CarrierCode = "UPS"
+	assert.Equal(t, 21.0, estimateCarrierShipment(shipment, factors).KgCO2e, "default carrier factor")
+
+	shipment.DistanceKm = nil
+	unknown := estimateCarrierShipment(shipment, factors)
+	assert.False(t, unknown.Estimated)
+	assert.NotEmpty(t, unknown.Reason)
+}
+
+func TestSustainabilityReport(t *testing.T) {
+	acme, globex := uuid.New(), uuid.New()
+	march := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	carrier := emissionShipment(&globex, 1000)
+	carrier.CustomerName = "Globex"
+	carrier.DistanceKm = floatPtr(100)
+	carrier.Date = april
+	unestimated := emissionShipment(&acme, 10)
+	unestimated.Date = april
+
+	repo := &fakeEmissionRepo{
+		trips: []deliverytypes.EmissionTrip{
+			{
+				RouteID:       uuid.New(),
+				VehicleType:   "truck",
+				GPSDistanceKm: 100,
+				Date:          march,
+				Shipments:     []deliverytypes.EmissionShipment{emissionShipment(&acme, 50), emissionShipment(&acme, 50)},
+			},
+			{RouteID: uuid.New(), VehicleType: "van", GPSDistanceKm: 40, Date: april},
+		},
+		carriers: []deliverytypes.EmissionShipment{carrier, unestimated},
+	}
+	svc := NewDeliveryEmissionService(repo)
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	report, err := svc.GetSustainabilityReport(ctx, deliverytypes.SustainabilityReportFilter{OrganizationID: uuid.New(), From: from, To: to})
+	require.NoError(t, err)
+	assert.Equal(t, deliverytypes.EmissionPeriodMonth, report.Period)
+	assert.Equal(t, 90+10+10.5, report.KgCO2e)
+	assert.Equal(t, 140.0, report.FleetDistanceKm)
+	assert.Equal(t, 2, report.Routes)
+	assert.Equal(t, 4, report.Shipments)
+	assert.Equal(t, 1, report.UnestimatedShipments)
+	assert.Equal(t, 100.0, report.ByMethod[deliverytypes.EmissionMethodFleet])
+	assert.Equal(t, 10.5, report.ByMethod[deliverytypes.EmissionMethodCarrier])
+	assert.Equal(t, []deliverytypes.EmissionPeriodTotal{
+		{PeriodStart: from, KgCO2e: 90, Shipments: 2},
+		{PeriodStart: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), KgCO2e: 20.5, Shipments: 2},
+	}, report.ByPeriod)
+	require.Len(t, report.ByCustomer, 2)
+	assert.Equal(t, &acme, report.ByCustomer[0].CustomerID)
+	assert.Equal(t, 90.0, report.ByCustomer[0].KgCO2e)
+	assert.Equal(t, 3, report.ByCustomer[0].Shipments)
+
+	report, err = svc.GetSustainabilityReport(ctx, deliverytypes.SustainabilityReportFilter{OrganizationID: uuid.New(), From: from, To: to, CustomerID: &globex})
+	require.NoError(t, err)
+	assert.Equal(t, 10.5, report.KgCO2e)
+	assert.Zero(t, report.Routes, "routes without the customer's shipments are left out")
+	assert.Equal(t, 1, report.Shipments)
+}
+
+func TestSustainabilityReportValidation(t *testing.T) {
+	svc := NewDeliveryEmissionService(&fakeEmissionRepo{})
+	ctx := context.Background()
+	now := time.Now()
+
+	for name, filter := range map[string]deliverytypes.SustainabilityReportFilter{
+		"period":   {From: now.AddDate(0, -1, 0), To: now, Period: "quarter"},
+		"reversed": {From: now, To: now.AddDate(0, -1, 0)},
+		"too long": {From: now.AddDate(-2, 0, 0), To: now},
+	} {
+		_, err := svc.GetSustainabilityReport(ctx, filter)
+		assert.ErrorIs(t, err, ErrInvalidEmissionReport, name)
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	sunday := time.Date(2025, 3, 16, 23, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC), periodStart(sunday, deliverytypes.EmissionPeriodDay))
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), periodStart(sunday, deliverytypes.EmissionPeriodWeek))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), periodStart(sunday, deliverytypes.EmissionPeriodMonth))
+}
+
+func TestUpdateEmissionFactors(t *testing.T) {
+	repo := &fakeEmissionRepo{}
+	svc := NewDeliveryEmissionService(repo)
+	ctx := context.Background()
+
+	_, err := svc.UpdateFactors(ctx, uuid.New(), deliverytypes.EmissionFactors{VehicleKgPerKm: map[string]float64{"hovercraft": 1}})
+	assert.ErrorIs(t, err, ErrInvalidEmissionReport)
+	_, err = svc.UpdateFactors(ctx, uuid.New(), deliverytypes.EmissionFactors{CarrierKgPerTonneKm: map[string]float64{"DHL": -1}})
+	assert.ErrorIs(t, err, ErrInvalidEmissionReport)
+
+	factors, err := svc.UpdateFactors(ctx, uuid.New(), deliverytypes.EmissionFactors{VehicleKgPerKm: map[string]float64{"van": 0.05}})
+	require.NoError(t, err)
+	assert.Equal(t, 0.05, factors.VehicleKgPerKm["van"])
+	assert.Equal(t, 0.9, factors.VehicleKgPerKm["truck"], "unset factors keep their default")
+	assert.Equal(t, DefaultCarrierKgPerTonneKm, factors.DefaultCarrierKgPerTonneKm)
+}
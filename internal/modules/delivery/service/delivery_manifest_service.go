@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
+
+	"github.com/google/uuid"
+)
+
+// ErrManifestPDFUnavailable is returned for PDF manifests when no PDF renderer is configured
+var ErrManifestPDFUnavailable = errors.New("PDF rendering is not available")
+
+// PDFRenderer converts HTML to PDF; *templates.PDFGenerator satisfies it
+type PDFRenderer interface {
+	HTMLToPDF(html string, opts *templates.PDFOptions) ([]byte, error)
+}
+
+type DeliveryManifestService struct {
+	repo     deliveryrepository.DeliveryManifestRepository
+	renderer PDFRenderer
+	now      func() time.Time
+}
+
+func NewDeliveryManifestService(repo deliveryrepository.DeliveryManifestRepository) *DeliveryManifestService {
+	return &DeliveryManifestService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// SetPDFRenderer enables PDF manifests
+func (s *DeliveryManifestService) SetPDFRenderer(renderer PDFRenderer) {
+	s.renderer = renderer
+}
+
+// IsHazmatRoute reports whether any shipment stopping on the route carries
+// dangerous goods
+func (s *DeliveryManifestService) IsHazmatRoute(ctx context.Context, orgID, routeID uuid.UUID) (bool, error) {
+	items, err := s.repo.FindManifestItems(ctx, orgID, routeID)
+	if err != nil {
+		return false, err
+	}
+	for _, stopItems := range items {
+		for _, item := range stopItems {
+			if item.IsHazmat() {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// GetRouteManifest builds the manifest of a route. It returns nil when the
+// organization has no such route.
+func (s *DeliveryManifestService) GetRouteManifest(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.RouteManifest, error) {
+	route, err := s.repo.FindRoute(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+	if route == nil {
+		return nil, nil
+	}
+
+	vehicle, err := s.repo.FindRouteVehicle(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+	stops, err := s.repo.FindManifestStops(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.FindManifestItems(ctx, orgID, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &deliverytypes.RouteManifest{
+		RouteID:          route.ID,
+		OrganizationID:   route.OrganizationID,
+		RouteName:        route.Name,
+		RouteCode:        route.RouteCode,
+		Status:           route.Status,
+		ScheduledStartAt: route.ScheduledStartAt,
+		Vehicle:          vehicle,
+		Stops:            make([]deliverytypes.ManifestStop, 0, len(stops)),
+		GeneratedAt:      s.now(),
+	}
+
+	unmeasured := 0
+	for _, stop := range stops {
+		stop.Items = items[stop.StopID]
+		if stop.Items == nil {
+			stop.Items = []deliverytypes.ManifestItem{}
+		}
+		for _, item := range stop.Items {
+			if item.Weight != nil {
+				stop.Weight += *item.Weight
+			}
+			if item.Volume != nil {
+				stop.Volume += *item.Volume
+			}
+			if item.Weight == nil || item.Volume == nil {
+				unmeasured++
+			}
+			if item.IsHazmat() {
+				stop.Hazmat = true
+			}
+		}
+
+		manifest.TotalItems += len(stop.Items)
+		manifest.TotalWeight += stop.Weight
+		manifest.TotalVolume += stop.Volume
+		if stop.Hazmat {
+			manifest.HazmatStops++
+		}
+		if stop.ShipmentID == nil && stop.Status == deliverytypes.StopStatusPlanned {
+			manifest.Warnings = append(manifest.Warnings, fmt.Sprintf("Stop %d has no shipment", stop.Sequence))
+		}
+		manifest.Stops = append(manifest.Stops, stop)
+	}
+
+	if unmeasured > 0 {
+		manifest.Warnings = append(manifest.Warnings, fmt.Sprintf("%d item(s) have no weight or volume; totals are understated", unmeasured))
+	}
+	if vehicle == nil {
+		manifest.Warnings = append(manifest.Warnings, "No vehicle is assigned to the route")
+	}
+	manifest.LoadingOrder = planLoadingOrder(manifest.Stops)
+
+	return manifest, nil
+}
+
+// planLoadingOrder sequences the load in reverse delivery order, so the
+// first stop's goods are loaded last and sit at the door. Stops that no
+// longer need loading are left out.
+func planLoadingOrder(stops []deliverytypes.ManifestStop) []deliverytypes.ManifestLoadRow {
+	rows := []deliverytypes.ManifestLoadRow{}
+	for i := len(stops) - 1; i >= 0; i-- {
+		stop := stops[i]
+		if len(stop.Items) == 0 {
+			continue
+		}
+		switch stop.Status {
+		case deliverytypes.StopStatusCompleted, deliverytypes.StopStatusSkipped, deliverytypes.StopStatusFailed:
+			continue
+		}
+
+		rows = append(rows, deliverytypes.ManifestLoadRow{
+			Position:       len(rows) + 1,
+			StopID:         stop.StopID,
+			StopSequence:   stop.Sequence,
+			TrackingNumber: stop.TrackingNumber,
+			Items:          len(stop.Items),
+			Weight:         stop.Weight,
+			Volume:         stop.Volume,
+			Hazmat:         stop.Hazmat,
+		})
+	}
+	return rows
+}
+
+// RenderRouteManifestPDF renders the manifest with the built-in layout. It
+// returns nil when the organization has no such route.
+func (s *DeliveryManifestService) RenderRouteManifestPDF(ctx context.Context, orgID, routeID uuid.UUID) ([]byte, *deliverytypes.RouteManifest, error) {
+	if s.renderer == nil {
+		return nil, nil, ErrManifestPDFUnavailable
+	}
+
+	manifest, err := s.GetRouteManifest(ctx, orgID, routeID)
+	if err != nil || manifest == nil {
+		return nil, manifest, err
+	}
+
+	var html bytes.Buffer
+	if err := manifestTemplate.Execute(&html, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to render manifest: %w", err)
+	}
+
+	opts := templates.DefaultPDFOptions()
+	opts.Orientation = "Landscape"
+	pdf, err := s.renderer.HTMLToPDF(html.String(), opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render manifest PDF: %w", err)
+	}
+	return pdf, manifest, nil
+}
+
+// Load returns the manifest as template data, so organizations can print it
+// with their own branded document templates
+func (s *DeliveryManifestService) Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error) {
+	manifest, err := s.GetRouteManifest(ctx, orgID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("delivery route %s not found", recordID)
+	}
+
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return data, nil
+}
+
+// addressKeys are the stop address fields printed, in order
+var addressKeys = []string{"name", "street", "street2", "city", "zip", "postal_code", "state", "region", "country"}
+
+// formatAddress prints a stop address on one line
+func formatAddress(address map[string]interface{}) string {
+	var parts []string
+	for _, key := range addressKeys {
+		if value, ok := address[key].(string); ok && strings.TrimSpace(value) != "" {
+			parts = append(parts, strings.TrimSpace(value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+var manifestTemplate = template.Must(template.New("route_manifest").Funcs(template.FuncMap{
+	"address": formatAddress,
+	"measure": func(v *float64) string {
+		if v == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.2f", *v)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+	body { font-family: Helvetica, Arial, sans-serif; font-size: 11px; }
+	h1 { font-size: 18px; margin: 0 0 4px; }
+	h2 { font-size: 14px; margin: 16px 0 4px; }
+	table { width: 100%; border-collapse: collapse; margin-bottom: 8px; }
+	th, td { border: 1px solid #999; padding: 3px 5px; text-align: left; vertical-align: top; }
+	th { background: #eee; }
+	.num { text-align: right; }
+	.hazmat { color: #b00; font-weight: bold; }
+	.warning { color: #a60; }
+</style>
+</head>
+<body>
+<h1>Route manifest: {{.RouteName}}{{if .RouteCode}} ({{.RouteCode}}){{end}}</h1>
+<p>
+	{{if .ScheduledStartAt}}Scheduled start: {{.ScheduledStartAt.Format "2006-01-02 15:04"}} &middot; {{end}}
+	{{if .Vehicle}}Vehicle: {{.Vehicle.Name}}{{if .Vehicle.RegistrationNumber}} ({{.Vehicle.RegistrationNumber}}){{end}} &middot; {{end}}
+	Stops: {{len .Stops}} &middot; Items: {{.TotalItems}} &middot;
+	Weight: {{printf "%.2f" .TotalWeight}} &middot; Volume: {{printf "%.2f" .TotalVolume}}
+	{{if .HazmatStops}}&middot; <span class="hazmat">Dangerous goods on {{.HazmatStops}} stop(s)</span>{{end}}
+</p>
+{{range .Warnings}}<p class="warning">{{.}}</p>{{end}}
+
+<h2>Loading order</h2>
+<table>
+	<tr><th>Load</th><th>Stop</th><th>Tracking</th><th class="num">Items</th><th class="num">Weight</th><th class="num">Volume</th><th>Hazmat</th></tr>
+	{{range .LoadingOrder}}
+	<tr>
+		<td>{{.Position}}</td><td>{{.StopSequence}}</td><td>{{.TrackingNumber}}</td><td class="num">{{.Items}}</td>
+		<td class="num">{{printf "%.2f" .Weight}}</td><td class="num">{{printf "%.2f" .Volume}}</td>
+		<td>{{if .Hazmat}}<span class="hazmat">Yes</span>{{end}}</td>
+	</tr>
+	{{end}}
+</table>
+
+{{range .Stops}}
+<h2>Stop {{.Sequence}}{{if .ContactName}}: {{.ContactName}}{{end}}</h2>
+<p>
+	{{address .Address}}
+	{{if .PlannedArrivalAt}}<br>Planned arrival: {{.PlannedArrivalAt.Format "2006-01-02 15:04"}}{{end}}
+	{{if .TrackingNumber}}<br>Tracking: {{.TrackingNumber}}{{end}}{{if .PickingName}} &middot; Picking: {{.PickingName}}{{end}}
+	{{if .RequiresSignature}}<br><strong>Signature required</strong>{{end}}
+	{{if .Notes}}<br>Notes: {{.Notes}}{{end}}
+</p>
+<table>
+	<tr><th>Product</th><th>Code</th><th class="num">Qty</th><th class="num">Weight</th><th class="num">Volume</th><th>Hazmat</th></tr>
+	{{range .Items}}
+	<tr>
+		<td>{{.ProductName}}</td><td>{{.ProductCode}}</td><td class="num">{{printf "%.2f" .Quantity}}</td>
+		<td class="num">{{measure .Weight}}</td><td class="num">{{measure .Volume}}</td>
+		<td>{{if .IsHazmat}}<span class="hazmat">{{if .UNNumber}}{{.UNNumber}} {{end}}{{if .HazmatClass}}Class {{.HazmatClass}}{{end}}</span>{{if .HazmatNotes}}<br>{{.HazmatNotes}}{{end}}{{end}}</td>
+	</tr>
+	{{else}}
+	<tr><td colspan="6">No items</td></tr>
+	{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeManifestRepo struct {
+	route   *deliverytypes.DeliveryRoute
+	vehicle *deliverytypes.ManifestVehicle
+	stops   []deliverytypes.ManifestStop
+	items   map[uuid.UUID][]deliverytypes.ManifestItem
+}
+
+func (f *fakeManifestRepo) FindRoute(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.DeliveryRoute, error) {
+	if f.route == nil || f.route.ID != routeID || f.route.OrganizationID != orgID {
+		return nil, nil
+	}
+	return f.route, nil
+}
+
+func (f *fakeManifestRepo) FindRouteVehicle(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.ManifestVehicle, error) {
+	return f.vehicle, nil
+}
+
+func (f *fakeManifestRepo) FindManifestStops(ctx context.Context, orgID, routeID uuid.UUID) ([]deliverytypes.ManifestStop, error) {
+	return f.stops, nil
+}
+
+func (f *fakeManifestRepo) FindManifestItems(ctx context.Context, orgID, routeID uuid.UUID) (map[uuid.UUID][]deliverytypes.ManifestItem, error) {
+	return f.items, nil
+}
+
+type fakePDFRenderer struct {
+	html string
+}
+
+func (r *fakePDFRenderer) HTMLToPDF(html string, opts *templates.PDFOptions) ([]byte, error) {
+	r.html = html
+	return []byte("%PDF"), nil
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func newManifestFixture() *fakeManifestRepo {
+	orgID := uuid.New()
+	stops := []deliverytypes.ManifestStop{
+		{StopID: uuid.New(), Sequence: 1, Status: deliverytypes.StopStatusCompleted, ShipmentID: &uuid.UUID{}, TrackingNumber: "T1"},
+		{StopID: uuid.New(), Sequence: 2, Status: deliverytypes.StopStatusPlanned, ShipmentID: &uuid.UUID{}, TrackingNumber: "T2",
+			Address: map[string]interface{}{"street": "1 Main St", "city": "Springfield"}},
+		{StopID: uuid.New(), Sequence: 3, Status: deliverytypes.StopStatusPlanned, ShipmentID: &uuid.UUID{}, TrackingNumber: "T3"},
+		{StopID: uuid.New(), Sequence: 4, Status: deliverytypes.StopStatusPlanned},
+	}
+	return &fakeManifestRepo{
+		route: &deliverytypes.DeliveryRoute{ID: uuid.New(), OrganizationID: orgID, Name: "North loop", RouteCode: "N-1"},
+		stops: stops,
+		items: map[uuid.UUID][]deliverytypes.ManifestItem{
+			stops[0].StopID: {{ProductName: "Crate", Quantity: 1, Weight: floatPtr(5), Volume: floatPtr(1)}},
+			stops[1].StopID: {
+				{ProductName: "Paint", Quantity: 2, Weight: floatPtr(10), Volume: floatPtr(0.5), UNNumber: "UN1263", HazmatClass: "3"},
+				{ProductName: "Brushes", Quantity: 4},
+			},
+			stops[2].StopID: {{ProductName: "Tiles", Quantity: 10, Weight: floatPtr(40), Volume: floatPtr(2)}},
+		},
+	}
+}
+
+func TestGetRouteManifest(t *testing.T) {
+	repo := newManifestFixture()
+	svc := NewDeliveryManifestService(repo)
+
+	manifest, err := svc.GetRouteManifest(context.Background(), repo.route.OrganizationID, repo.route.ID)
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+
+	require.Len(t, manifest.Stops, 4)
+	assert.Equal(t, 4, manifest.TotalItems)
+	assert.Equal(t, 55.0, manifest.TotalWeight)
+	assert.Equal(t, 1, manifest.HazmatStops)
+	assert.True(t, manifest.Stops[1].Hazmat)
+	assert.Equal(t, 10.0, manifest.Stops[1].Weight)
+	assert.NotNil(t, manifest.Stops[3].Items, "stops without items list an empty slice")
+
+	assert.Contains(t, manifest.Warnings, "Stop 4 has no shipment")
+	assert.Contains(t, manifest.Warnings, "1 item(s) have no weight or volume; totals are understated")
+	assert.Contains(t, manifest.Warnings, "No vehicle is assigned to the route")
+}
+
+func TestGetRouteManifestOtherOrganization(t *testing.T) {
+	repo := newManifestFixture()
+	svc := NewDeliveryManifestService(repo)
+
+	manifest, err := svc.GetRouteManifest(context.Background(), uuid.New(), repo.route.ID)
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+}
+
+func TestPlanLoadingOrderReversesDeliverySequence(t *testing.T) {
+	repo := newManifestFixture()
+	svc := NewDeliveryManifestService(repo)
+
+	manifest, err := svc.GetRouteManifest(context.Background(), repo.route.OrganizationID, repo.route.ID)
+	require.NoError(t, err)
+
+	// The completed stop and the stop without items are not loaded
+	require.Len(t, manifest.LoadingOrder, 2)
+	assert.Equal(t, 1, manifest.LoadingOrder[0].Position)
+	assert.Equal(t, 3, manifest.LoadingOrder[0].StopSequence)
+	assert.Equal(t, 2, manifest.LoadingOrder[1].Position)
+	assert.Equal(t, 2, manifest.LoadingOrder[1].StopSequence)
+	assert.True(t, manifest.LoadingOrder[1].Hazmat)
+}
+
+func TestRenderRouteManifestPDF(t *testing.T) {
+	repo := newManifestFixture()
+	svc := NewDeliveryManifestService(repo)
+
+	_, _, err := svc.RenderRouteManifestPDF(context.Background(), repo.route.OrganizationID, repo.route.ID)
+	assert.ErrorIs(t, err, ErrManifestPDFUnavailable)
+
+	renderer := &fakePDFRenderer{}
+	svc.SetPDFRenderer(renderer)
+	svc.now = func() time.Time { return time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC) }
+
+	pdf, manifest, err := svc.RenderRouteManifestPDF(context.Background(), repo.route.OrganizationID, repo.route.ID)
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	assert.Equal(t, []byte("%PDF"), pdf)
+	assert.Contains(t, renderer.html, "North loop")
+	assert.Contains(t, renderer.html, "1 Main St, Springfield")
+	assert.Contains(t, renderer.html, "UN1263")
+	assert.Less(t, strings.Index(renderer.html, "Stop 2"), strings.Index(renderer.html, "Stop 3"))
+}
+
+func TestLoadExposesManifestAsTemplateData(t *testing.T) {
+	repo := newManifestFixture()
+	svc := NewDeliveryManifestService(repo)
+
+	data, err := svc.Load(context.Background(), repo.route.OrganizationID, repo.route.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "North loop", data["route_name"])
+	assert.Len(t, data["stops"], 4)
+
+	_, err = svc.Load(context.Background(), repo.route.OrganizationID, uuid.New())
+	assert.Error(t, err)
+}
+
+// fakeDriverQualifier qualifies the listed employees only
+type fakeDriverQualifier struct {
+	qualified map[uuid.UUID]bool
+	checked   int
+}
+
+var errDriverNotQualified = errors.New("not qualified")
+
+func (f *fakeDriverQualifier) RequireHazmatDriver(ctx context.Context, orgID, employeeID uuid.UUID) error {
+	f.checked++
+	if !f.qualified[employeeID] {
+		return errDriverNotQualified
+	}
+	return nil
+}
+
+func TestCheckHazmatDriver(t *testing.T) {
+	repo := newManifestFixture()
+	qualifiedID, unqualifiedID := uuid.New(), uuid.New()
+	drivers := &fakeDriverQualifier{qualified: map[uuid.UUID]bool{qualifiedID: true}}
+	svc := &DeliveryTrackingService{}
+	ctx := context.Background()
+	orgID, routeID := repo.route.OrganizationID, repo.route.ID
+
+	require.NoError(t, svc.checkHazmatDriver(ctx, orgID, routeID, unqualifiedID), "no qualifier set")
+
+	svc.SetDriverQualifier(NewDeliveryManifestService(repo), drivers)
+	assert.ErrorIs(t, svc.checkHazmatDriver(ctx, orgID, routeID, unqualifiedID), errDriverNotQualified)
+	assert.NoError(t, svc.checkHazmatDriver(ctx, orgID, routeID, qualifiedID))
+
+	// Without dangerous goods the driver is not checked
+	delete(repo.items, repo.stops[1].StopID)
+	drivers.checked = 0
+	assert.NoError(t, svc.checkHazmatDriver(ctx, orgID, routeID, unqualifiedID))
+	assert.Zero(t, drivers.checked)
+}
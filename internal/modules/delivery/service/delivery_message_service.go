@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/realtime"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxMessageLength is the longest message body, in characters
+	MaxMessageLength = 4000
+	// DefaultMessagePageSize is the number of messages returned when no limit is given
+	DefaultMessagePageSize = 50
+	// MaxMessagePageSize caps the number of messages returned at once
+	MaxMessagePageSize = 200
+	// pushTimeout bounds a push notification, which is sent after the request returns
+	pushTimeout = 15 * time.Second
+	// pushPreviewLength is how much of a message a push notification shows
+	pushPreviewLength = 140
+)
+
+// ErrInvalidMessage is returned for messages that fail validation
+var ErrInvalidMessage = errors.New("invalid message")
+
+type DeliveryMessageService struct {
+	repo     deliveryrepository.DeliveryMessageRepository
+	hub      *realtime.Hub
+	notifier push.Notifier
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+func NewDeliveryMessageService(repo deliveryrepository.DeliveryMessageRepository, hub *realtime.Hub, logger *slog.Logger) *DeliveryMessageService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &DeliveryMessageService{
+		repo:   repo,
+		hub:    hub,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SetPushNotifier sends new messages as push notifications to the other side
+// of the conversation. Without a notifier messages only reach connected clients.
+func (s *DeliveryMessageService) SetPushNotifier(notifier push.Notifier) {
+	s.notifier = notifier
+}
+
+// routeTopic is the realtime topic of a route's messages
+func routeTopic(orgID, routeID uuid.UUID) string {
+	return fmt.Sprintf("delivery:%s:route:%s:messages", orgID, routeID)
+}
+
+// SendMessage posts a message to a route. The sender is the driver when they
+// drive the route and a dispatcher otherwise. It returns nil when the
+// organization has no such route.
+func (s *DeliveryMessageService) SendMessage(ctx context.Context, orgID, userID, routeID uuid.UUID, req deliverytypes.DeliveryMessageRequest) (*deliverytypes.DeliveryMessage, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" && req.AttachmentID == nil {
+		return nil, fmt.Errorf("%w: a message needs a body or a photo", ErrInvalidMessage)
+	}
+	if utf8.RuneCountInString(body) > MaxMessageLength {
+		return nil, fmt.Errorf("%w: body is longer than %d characters", ErrInvalidMessage, MaxMessageLength)
+	}
+
+	participants, err := s.repo.FindRouteParticipants(ctx, orgID, routeID)
+	if err != nil || participants == nil {
+		return nil, err
+	}
+
+	if req.StopID != nil {
+		onRoute, err := s.repo.StopBelongsToRoute(ctx, orgID, routeID, *req.StopID)
+		if err != nil {
+			return nil, err
+		}
+		if !onRoute {
+			return nil, fmt.Errorf("%w: stop %s is not on the route", ErrInvalidMessage, req.StopID)
+		}
+	}
+
+	if req.AttachmentID != nil {
+		mimeType, err := s.repo.FindAttachmentMimeType(ctx, orgID, *req.AttachmentID)
+		if err != nil {
+			return nil, err
+		}
+		if mimeType == "" {
+			return nil, fmt.Errorf("%w: attachment %s not found", ErrInvalidMessage, req.AttachmentID)
+		}
+		if !strings.HasPrefix(mimeType, "image/") {
+			return nil, fmt.Errorf("%w: attachment is %s, not a photo", ErrInvalidMessage, mimeType)
+		}
+	}
+
+	role := deliverytypes.MessageSenderDispatcher
+	recipients := participants.DriverUserIDs
+	if containsUUID(participants.DriverUserIDs, userID) {
+		role = deliverytypes.MessageSenderDriver
+		recipients = participants.DispatcherUserIDs
+	}
+
+	message, err := s.repo.CreateMessage(ctx, deliverytypes.DeliveryMessage{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		RouteID:        routeID,
+		StopID:         req.StopID,
+		SenderID:       userID,
+		SenderRole:     role,
+		Body:           body,
+		AttachmentID:   req.AttachmentID,
+		CreatedAt:      s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.hub != nil {
+		s.hub.Publish(routeTopic(orgID, routeID), deliverytypes.MessageEventCreated, message)
+	}
+	s.notify(*message, recipients)
+
+	return message, nil
+}
+
+// notify pushes the message to its recipients in the background; the
+// message is already stored and streamed, so failures are only logged
+func (s *DeliveryMessageService) notify(message deliverytypes.DeliveryMessage, recipients []uuid.UUID) {
+	if s.notifier == nil {
+		return
+	}
+
+	var userIDs []uuid.UUID
+	for _, id := range recipients {
+		if id != message.SenderID && !containsUUID(userIDs, id) {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	title := "Message from dispatch"
+	if message.SenderRole == deliverytypes.MessageSenderDriver {
+		title = "Message from driver"
+	}
+	body := message.Body
+	if utf8.RuneCountInString(body) > pushPreviewLength {
+		body = string([]rune(body)[:pushPreviewLength-1]) + "…"
+	}
+	if body == "" {
+		body = "Sent a photo"
+	}
+
+	notification := push.Notification{
+		OrganizationID: message.OrganizationID,
+		UserIDs:        userIDs,
+		Title:          title,
+		Body:           body,
+		Data: map[string]string{
+			"type":       "delivery_message",
+			"route_id":   message.RouteID.String(),
+			"message_id": message.ID.String(),
+		},
+	}
+	if message.StopID != nil {
+		notification.Data["stop_id"] = message.StopID.String()
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		defer cancel()
+		if err := s.notifier.Send(ctx, notification); err != nil {
+			s.logger.Warn("Failed to push delivery message", "error", err, "message_id", message.ID, "route_id", message.RouteID)
+		}
+	}()
+}
+
+// ListMessages returns the route's message history, newest first. It returns
+// nil when the organization has no such route.
+func (s *DeliveryMessageService) ListMessages(ctx context.Context, filter deliverytypes.DeliveryMessageFilter) ([]deliverytypes.DeliveryMessage, error) {
+	participants, err := s.repo.FindRouteParticipants(ctx, filter.OrganizationID, filter.RouteID)
+	if err != nil || participants == nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultMessagePageSize
+	}
+	if filter.Limit > MaxMessagePageSize {
+		filter.Limit = MaxMessagePageSize
+	}
+
+	return s.repo.FindMessages(ctx, filter)
+}
+
+// MarkRead records read receipts for the user and streams them to the route.
+// It returns nil when the organization has no such route.
+func (s *DeliveryMessageService) MarkRead(ctx context.Context, orgID, userID, routeID uuid.UUID, req deliverytypes.MessageReadRequest) (*deliverytypes.MessageReadReceipt, error) {
+	participants, err := s.repo.FindRouteParticipants(ctx, orgID, routeID)
+	if err != nil || participants == nil {
+		return nil, err
+	}
+
+	readAt := s.now()
+	marked, err := s.repo.MarkRead(ctx, orgID, routeID, userID, req.MessageIDs, readAt)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &deliverytypes.MessageReadReceipt{
+		RouteID:    routeID,
+		UserID:     userID,
+		MessageIDs: marked,
+		ReadAt:     readAt,
+	}
+	if len(marked) > 0 && s.hub != nil {
+		s.hub.Publish(routeTopic(orgID, routeID), deliverytypes.MessageEventRead, receipt)
+	}
+
+	return receipt, nil
+}
+
+// Subscribe streams the route's new messages and read receipts. It returns
+// nil when the organization has no such route.
+func (s *DeliveryMessageService) Subscribe(ctx context.Context, orgID, routeID uuid.UUID) (*realtime.Subscription, error) {
+	if s.hub == nil {
+		return nil, fmt.Errorf("realtime messaging is not available")
+	}
+
+	participants, err := s.repo.FindRouteParticipants(ctx, orgID, routeID)
+	if err != nil || participants == nil {
+		return nil, err
+	}
+
+	return s.hub.Subscribe(routeTopic(orgID, routeID)), nil
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
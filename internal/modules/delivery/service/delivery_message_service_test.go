@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/realtime"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessageRepo struct {
+	routeID      uuid.UUID
+	participants deliverytypes.RouteParticipants
+	stops        map[uuid.UUID]bool
+	attachments  map[uuid.UUID]string
+	messages     []deliverytypes.DeliveryMessage
+	reads        map[uuid.UUID]map[uuid.UUID]bool
+	lastFilter   deliverytypes.DeliveryMessageFilter
+}
+
+func (f *fakeMessageRepo) FindRouteParticipants(ctx context.Context, orgID, routeID uuid.UUID) (*deliverytypes.RouteParticipants, error) {
+	if routeID != f.routeID {
+		return nil, nil
+	}
+	participants := f.participants
+	return &participants, nil
+}
+
+func (f *fakeMessageRepo) StopBelongsToRoute(ctx context.Context, orgID, routeID, stopID uuid.UUID) (bool, error) {
+	return f.stops[stopID], nil
+}
+
+func (f *fakeMessageRepo) FindAttachmentMimeType(ctx context.Context, orgID, attachmentID uuid.UUID) (string, error) {
+	return f.attachments[attachmentID], nil
+}
+
+func (f *fakeMessageRepo) CreateMessage(ctx context.Context, message deliverytypes.DeliveryMessage) (*deliverytypes.DeliveryMessage, error) {
+	message.Reads = []deliverytypes.MessageRead{}
+	f.messages = append(f.messages, message)
+	return &message, nil
+}
+
+func (f *fakeMessageRepo) FindMessages(ctx context.Context, filter deliverytypes.DeliveryMessageFilter) ([]deliverytypes.DeliveryMessage, error) {
+	f.lastFilter = filter
+	return []deliverytypes.DeliveryMessage{}, nil
+}
+
+func (f *fakeMessageRepo) MarkRead(ctx context.Context, orgID, routeID, userID uuid.UUID, messageIDs []uuid.UUID, readAt time.Time) ([]uuid.UUID, error) {
+	marked := []uuid.UUID{}
+	for _, m := range f.messages {
+		if m.SenderID == userID || f.reads[m.ID][userID] {
+			continue
+		}
+		if len(messageIDs) > 0 && !containsUUID(messageIDs, m.ID) {
+			continue
+		}
+		if f.reads[m.ID] == nil {
+			f.reads[m.ID] = make(map[uuid.UUID]bool)
+		}
+		f.reads[m.ID][userID] = true
+		marked = append(marked, m.ID)
+	}
+	return marked, nil
+}
+
+type fakeNotifier struct {
+	sent chan push.Notification
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, notification push.Notification) error {
+	n.sent <- notification
+	return nil
+}
+
+type messageFixture struct {
+	repo       *fakeMessageRepo
+	svc        *DeliveryMessageService
+	hub        *realtime.Hub
+	notifier   *fakeNotifier
+	orgID      uuid.UUID
+	driver     uuid.UUID
+	dispatcher uuid.UUID
+}
+
+func newMessageFixture() *messageFixture {
+	f := &messageFixture{orgID: uuid.New(), driver: uuid.New(), dispatcher: uuid.New()}
+	f.repo = &fakeMessageRepo{
+		routeID: uuid.New(),
+		participants: deliverytypes.RouteParticipants{
+			DriverUserIDs:     []uuid.UUID{f.driver},
+			DispatcherUserIDs: []uuid.UUID{f.dispatcher},
+		},
+		stops:       make(map[uuid.UUID]bool),
+		attachments: make(map[uuid.UUID]string),
+		reads:       make(map[uuid.UUID]map[uuid.UUID]bool),
+	}
+	f.hub = realtime.NewHub(8)
+	f.notifier = &fakeNotifier{sent: make(chan push.Notification, 1)}
+	f.svc = NewDeliveryMessageService(f.repo, f.hub, nil)
+	f.svc.SetPushNotifier(f.notifier)
+	return f
+}
+
+func (f *messageFixture) nextPush(t *testing.T) push.Notification {
+	select {
+	case n := <-f.notifier.sent:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("no push notification sent")
+		return push.Notification{}
+	}
+}
+
+func TestSendMessageFromDispatcher(t *testing.T) {
+	f := newMessageFixture()
+	sub, err := f.svc.Subscribe(context.Background(), f.orgID, f.repo.routeID)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	stopID := uuid.New()
+	f.repo.stops[stopID] = true
+	message, err := f.svc.SendMessage(context.Background(), f.orgID, f.dispatcher, f.repo.routeID, deliverytypes.DeliveryMessageRequest{
+		StopID: &stopID,
+		Body:   "  Use the loading dock at the back  ",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Equal(t, deliverytypes.MessageSenderDispatcher, message.SenderRole)
+	assert.Equal(t, "Use the loading dock at the back", message.Body)
+
+	event := <-sub.C
+	assert.Equal(t, deliverytypes.MessageEventCreated, event.Type)
+	assert.Equal(t, message.ID, event.Data.(*deliverytypes.DeliveryMessage).ID)
+
+	notification := f.nextPush(t)
+	assert.Equal(t, []uuid.UUID{f.driver}, notification.UserIDs)
+	assert.Equal(t, "Message from dispatch", notification.Title)
+	assert.Equal(t, stopID.String(), notification.Data["stop_id"])
+}
+
+func TestSendPhotoFromDriver(t *testing.T) {
+	f := newMessageFixture()
+	photoID := uuid.New()
+	f.repo.attachments[photoID] = "image/jpeg"
+
+	message, err := f.svc.SendMessage(context.Background(), f.orgID, f.driver, f.repo.routeID, deliverytypes.DeliveryMessageRequest{AttachmentID: &photoID})
+	require.NoError(t, err)
+	assert.Equal(t, deliverytypes.MessageSenderDriver, message.SenderRole)
+
+	notification := f.nextPush(t)
+	assert.Equal(t, []uuid.UUID{f.dispatcher}, notification.UserIDs)
+	assert.Equal(t, "Sent a photo", notification.Body)
+}
+
+func TestSendMessageValidation(t *testing.T) {
+	f := newMessageFixture()
+	ctx := context.Background()
+	documentID := uuid.New()
+	f.repo.attachments[documentID] = "application/pdf"
+	missingID := uuid.New()
+	otherStop := uuid.New()
+
+	for name, req := range map[string]deliverytypes.DeliveryMessageRequest{
+		"empty":         {Body: "   "},
+		"not a photo":   {AttachmentID: &documentID},
+		"no attachment": {AttachmentID: &missingID},
+		"foreign stop":  {Body: "Here", StopID: &otherStop},
+	} {
+		_, err := f.svc.SendMessage(ctx, f.orgID, f.driver, f.repo.routeID, req)
+		assert.ErrorIs(t, err, ErrInvalidMessage, name)
+	}
+	assert.Empty(t, f.repo.messages)
+
+	message, err := f.svc.SendMessage(ctx, f.orgID, f.driver, uuid.New(), deliverytypes.DeliveryMessageRequest{Body: "Hello"})
+	require.NoError(t, err)
+	assert.Nil(t, message, "unknown route")
+}
+
+func TestMarkReadStreamsReceipts(t *testing.T) {
+	f := newMessageFixture()
+	f.svc.SetPushNotifier(nil)
+	ctx := context.Background()
+
+	first, err := f.svc.SendMessage(ctx, f.orgID, f.dispatcher, f.repo.routeID, deliverytypes.DeliveryMessageRequest{Body: "Call me"})
+	require.NoError(t, err)
+	_, err = f.svc.SendMessage(ctx, f.orgID, f.driver, f.repo.routeID, deliverytypes.DeliveryMessageRequest{Body: "On it"})
+	require.NoError(t, err)
+
+	sub, err := f.svc.Subscribe(ctx, f.orgID, f.repo.routeID)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	receipt, err := f.svc.MarkRead(ctx, f.orgID, f.driver, f.repo.routeID, deliverytypes.MessageReadRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first.ID}, receipt.MessageIDs, "own messages are not marked")
+
+	event := <-sub.C
+	assert.Equal(t, deliverytypes.MessageEventRead, event.Type)
+
+	receipt, err = f.svc.MarkRead(ctx, f.orgID, f.driver, f.repo.routeID, deliverytypes.MessageReadRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, receipt.MessageIDs)
+	assert.Empty(t, sub.C, "nothing new to stream")
+}
+
+func TestListMessagesClampsLimit(t *testing.T) {
+	f := newMessageFixture()
+	ctx := context.Background()
+
+	_, err := f.svc.ListMessages(ctx, deliverytypes.DeliveryMessageFilter{OrganizationID: f.orgID, RouteID: f.repo.routeID})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMessagePageSize, f.repo.lastFilter.Limit)
+
+	_, err = f.svc.ListMessages(ctx, deliverytypes.DeliveryMessageFilter{OrganizationID: f.orgID, RouteID: f.repo.routeID, Limit: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, MaxMessagePageSize, f.repo.lastFilter.Limit)
+
+	messages, err := f.svc.ListMessages(ctx, deliverytypes.DeliveryMessageFilter{OrganizationID: f.orgID, RouteID: uuid.New()})
+	require.NoError(t, err)
+	assert.Nil(t, messages)
+}
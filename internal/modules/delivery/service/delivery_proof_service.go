@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryProofService builds proofs of delivery. It is the data source of
+// proof of delivery document templates, so organizations print them with
+// their own branding, one at a time or for a whole route or period.
+type DeliveryProofService struct {
+	repo deliveryrepository.DeliveryProofRepository
+	now  func() time.Time
+}
+
+func NewDeliveryProofService(repo deliveryrepository.DeliveryProofRepository) *DeliveryProofService {
+	return &DeliveryProofService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// GetProof returns the proof of delivery of a shipment, or nil when the
+// organization has no such shipment
+func (s *DeliveryProofService) GetProof(ctx context.Context, orgID, shipmentID uuid.UUID) (*deliverytypes.DeliveryProof, error) {
+	proof, err := s.repo.FindProof(ctx, orgID, shipmentID)
+	if err != nil || proof == nil {
+		return nil, err
+	}
+
+	items, err := s.repo.FindProofItems(ctx, orgID, proof.PickingID)
+	if err != nil {
+		return nil, err
+	}
+	proof.Items = items
+	if proof.Items == nil {
+		proof.Items = []deliverytypes.ManifestItem{}
+	}
+	proof.GeneratedAt = s.now()
+	return proof, nil
+}
+
+// Load returns the proof of delivery of a shipment as template data
+func (s *DeliveryProofService) Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error) {
+	proof, err := s.GetProof(ctx, orgID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, fmt.Errorf("delivery shipment %s not found", recordID)
+	}
+	if proof.Status != deliverytypes.ShipmentStatusDelivered {
+		return nil, fmt.Errorf("delivery shipment %s is %s, not delivered", recordID, proof.Status)
+	}
+
+	raw, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proof of delivery: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode proof of delivery: %w", err)
+	}
+	return data, nil
+}
+
+// SelectRecords returns the delivered shipments of a route or arrived in a
+// date range, so all their proofs of delivery can be printed in one batch.
+// Files are named after the tracking number, or the picking without one.
+func (s *DeliveryProofService) SelectRecords(ctx context.Context, filter documenttypes.RecordFilter) ([]documenttypes.BatchItem, error) {
+	var to *time.Time
+	if filter.To != nil {
+		// The last day is included
+		end := filter.To.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	shipments, err := s.repo.ListDeliveredShipments(ctx, filter.OrganizationID, filter.RouteID, filter.From, to, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]documenttypes.BatchItem, 0, len(shipments))
+	for _, shipment := range shipments {
+		name := shipment.TrackingNumber
+		if name == "" {
+			name = shipment.PickingName
+		}
+		if name == "" {
+			name = shipment.ShipmentID.String()
+		}
+		items = append(items, documenttypes.BatchItem{RecordID: &shipment.ShipmentID, Filename: "POD-" + name})
+	}
+	return items, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProofRepo struct {
+	proof     *deliverytypes.DeliveryProof
+	items     []deliverytypes.ManifestItem
+	shipments []deliverytypes.DeliveredShipment
+	from, to  *time.Time
+}
+
+func (f *fakeProofRepo) FindProof(ctx context.Context, orgID, shipmentID uuid.UUID) (*deliverytypes.DeliveryProof, error) {
+	if f.proof == nil || f.proof.ShipmentID != shipmentID || f.proof.OrganizationID != orgID {
+		return nil, nil
+	}
+	proof := *f.proof
+	return &proof, nil
+}
+
+func (f *fakeProofRepo) FindProofItems(ctx context.Context, orgID, pickingID uuid.UUID) ([]deliverytypes.ManifestItem, error) {
+	return f.items, nil
+}
+
+func (f *fakeProofRepo) ListDeliveredShipments(ctx context.Context, orgID uuid.UUID, routeID *uuid.UUID, from, to *time.Time, limit int) ([]deliverytypes.DeliveredShipment, error) {
+	f.from, f.to = from, to
+	return f.shipments, nil
+}
+
+func TestProofLoad(t *testing.T) {
+	orgID := uuid.New()
+	repo := &fakeProofRepo{
+		proof: &deliverytypes.DeliveryProof{
+			ShipmentID:     uuid.New(),
+			OrganizationID: orgID,
+			TrackingNumber: "T1",
+			Status:         deliverytypes.ShipmentStatusInTransit,
+		},
+		items: []deliverytypes.ManifestItem{{ProductName: "Pallet", Quantity: 2}},
+	}
+	svc := NewDeliveryProofService(repo)
+
+	_, err := svc.Load(context.Background(), orgID, repo.proof.ShipmentID)
+	assert.ErrorContains(t, err, "not delivered")
+
+	repo.proof.Status = deliverytypes.ShipmentStatusDelivered
+	data, err := svc.Load(context.Background(), orgID, repo.proof.ShipmentID)
+	require.NoError(t, err)
+	assert.Equal(t, "T1", data["tracking_number"])
+	assert.Len(t, data["items"], 1)
+
+	_, err = svc.Load(context.Background(), uuid.New(), repo.proof.ShipmentID)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestProofSelectRecords(t *testing.T) {
+	repo := &fakeProofRepo{shipments: []deliverytypes.DeliveredShipment{
+		{ShipmentID: uuid.New(), TrackingNumber: "T1", PickingName: "WH/OUT/1"},
+		{ShipmentID: uuid.New(), PickingName: "WH/OUT/2"},
+	}}
+	svc := NewDeliveryProofService(repo)
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	items, err := svc.SelectRecords(context.Background(), documenttypes.RecordFilter{OrganizationID: uuid.New(), From: &from, To: &to, Limit: 10})
+	require.NoError(t, err)
+
+	require.Len(t, items, 2)
+	assert.Equal(t, "POD-T1", items[0].Filename)
+	assert.Equal(t, "POD-WH/OUT/2", items[1].Filename, "shipments without tracking number are named after the picking")
+	assert.Equal(t, repo.shipments[1].ShipmentID, *items[1].RecordID)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), *repo.to, "the last day is included")
+}
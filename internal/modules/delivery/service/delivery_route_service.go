@@ -7,14 +7,21 @@ import (
 
 	deliveryrepository "github.com/KevTiv/alieze-erp/internal/modules/delivery/repository"
 	deliverytypes "github.com/KevTiv/alieze-erp/internal/modules/delivery/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
 	"github.com/KevTiv/alieze-erp/pkg/events"
 
 	"github.com/google/uuid"
 )
 
+// BusinessCalendarResolver returns the business hours that apply to an organization
+type BusinessCalendarResolver interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*calendar.Calendar, error)
+}
+
 type DeliveryRouteService struct {
-	repo     deliveryrepository.DeliveryRouteRepository
-	eventBus *events.Bus
+	repo      deliveryrepository.DeliveryRouteRepository
+	eventBus  *events.Bus
+	calendars BusinessCalendarResolver
 }
 
 func NewDeliveryRouteService(repo deliveryrepository.DeliveryRouteRepository) *DeliveryRouteService {
@@ -29,6 +36,11 @@ func NewDeliveryRouteServiceWithEventBus(repo deliveryrepository.DeliveryRouteRe
 	return service
 }
 
+// SetBusinessCalendars makes route scheduling respect the organization's business hours
+func (s *DeliveryRouteService) SetBusinessCalendars(calendars BusinessCalendarResolver) {
+	s.calendars = calendars
+}
+
 func (s *DeliveryRouteService) CreateDeliveryRoute(ctx context.Context, route deliverytypes.DeliveryRoute) (*deliverytypes.DeliveryRoute, error) {
 	// Validate the route
 	if err := s.validateDeliveryRoute(route); err != nil {
@@ -48,6 +60,9 @@ func (s *DeliveryRouteService) CreateDeliveryRoute(ctx context.Context, route de
 	if route.Metadata == nil {
 		route.Metadata = make(map[string]interface{})
 	}
+	if err := s.alignSchedule(ctx, &route); err != nil {
+		return nil, err
+	}
 
 	// Create the route
 	createdRoute, err := s.repo.Create(ctx, route)
@@ -93,6 +108,9 @@ func (s *DeliveryRouteService) UpdateDeliveryRoute(ctx context.Context, route de
 	if existing == nil {
 		return nil, fmt.Errorf("delivery route not found")
 	}
+	if err := s.alignSchedule(ctx, &route); err != nil {
+		return nil, err
+	}
 
 	// Update the route
 	updatedRoute, err := s.repo.Update(ctx, route)
@@ -190,6 +208,38 @@ func (s *DeliveryRouteService) CompleteRoute(ctx context.Context, routeID uuid.U
 	return updatedRoute, nil
 }
 
+// alignSchedule moves a scheduled start that falls outside business hours to
+// the next opening. The planned duration is counted in working time from the
+// new start, so the scheduled end moves past closed periods as well.
+func (s *DeliveryRouteService) alignSchedule(ctx context.Context, route *deliverytypes.DeliveryRoute) error {
+	if s.calendars == nil || route.ScheduledStartAt == nil {
+		return nil
+	}
+
+	cal, err := s.calendars.Resolve(ctx, route.OrganizationID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to resolve business calendar: %w", err)
+	}
+
+	requested := *route.ScheduledStartAt
+	start, ok := cal.NextOpen(requested)
+	if !ok || start.Equal(requested) {
+		return nil
+	}
+
+	route.ScheduledStartAt = &start
+	if route.ScheduledEndAt != nil && route.ScheduledEndAt.After(requested) {
+		end := cal.Add(start, route.ScheduledEndAt.Sub(requested))
+		route.ScheduledEndAt = &end
+	}
+	if route.Metadata == nil {
+		route.Metadata = make(map[string]interface{})
+	}
+	route.Metadata["requested_start_at"] = requested
+
+	return nil
+}
+
 func (s *DeliveryRouteService) validateDeliveryRoute(route deliverytypes.DeliveryRoute) error {
 	if route.OrganizationID == uuid.Nil {
 		return fmt.Errorf("organization_id is required")
@@ -214,25 +264,21 @@ func (s *DeliveryRouteService) publishRouteEvent(ctx context.Context, eventType
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":                 route.ID,
-			"organization_id":    route.OrganizationID,
-			"name":               route.Name,
-			"route_code":         route.RouteCode,
-			"transport_mode":     route.TransportMode,
-			"status":             route.Status,
-			"scheduled_start_at": route.ScheduledStartAt,
-			"scheduled_end_at":   route.ScheduledEndAt,
-			"actual_start_at":    route.ActualStartAt,
-			"actual_end_at":      route.ActualEndAt,
-			"metadata":           route.Metadata,
-			"created_at":         route.CreatedAt,
-			"updated_at":         route.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":                 route.ID,
+		"organization_id":    route.OrganizationID,
+		"name":               route.Name,
+		"route_code":         route.RouteCode,
+		"transport_mode":     route.TransportMode,
+		"status":             route.Status,
+		"scheduled_start_at": route.ScheduledStartAt,
+		"scheduled_end_at":   route.ScheduledEndAt,
+		"actual_start_at":    route.ActualStartAt,
+		"actual_end_at":      route.ActualEndAt,
+		"metadata":           route.Metadata,
+		"created_at":         route.CreatedAt,
+		"updated_at":         route.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
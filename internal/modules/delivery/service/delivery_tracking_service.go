@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,9 +13,34 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrInvalidShipmentStatus is returned when a shipment is moved to an unknown
+// status or one its current status cannot move to
+var ErrInvalidShipmentStatus = errors.New("invalid shipment status")
+
+// CapacityChecker reports how a change to a route would overload its vehicle
+type CapacityChecker interface {
+	CheckRouteWithShipment(ctx context.Context, orgID, routeID, shipmentID uuid.UUID) ([]deliverytypes.CapacityViolation, error)
+	CheckRouteWithPicking(ctx context.Context, orgID, routeID, pickingID uuid.UUID) ([]deliverytypes.CapacityViolation, error)
+	CheckRouteWithVehicle(ctx context.Context, orgID, routeID, vehicleID uuid.UUID) ([]deliverytypes.CapacityViolation, error)
+}
+
+// HazmatDetector reports whether a route carries dangerous goods
+type HazmatDetector interface {
+	IsHazmatRoute(ctx context.Context, orgID, routeID uuid.UUID) (bool, error)
+}
+
+// DriverQualifier checks that an employee holds the certifications required
+// to drive routes carrying dangerous goods
+type DriverQualifier interface {
+	RequireHazmatDriver(ctx context.Context, orgID, employeeID uuid.UUID) error
+}
+
 type DeliveryTrackingService struct {
 	repo     deliveryrepository.DeliveryTrackingRepository
 	eventBus *events.Bus
+	capacity CapacityChecker
+	hazmat   HazmatDetector
+	drivers  DriverQualifier
 }
 
 func NewDeliveryTrackingService(repo deliveryrepository.DeliveryTrackingRepository) *DeliveryTrackingService {
@@ -29,6 +55,20 @@ func NewDeliveryTrackingServiceWithEventBus(repo deliveryrepository.DeliveryTrac
 	return service
 }
 
+// SetCapacityChecker rejects shipments, stops and vehicle assignments that
+// would overload a route's vehicle, unless the change carries the
+// capacity override flag in its metadata
+func (s *DeliveryTrackingService) SetCapacityChecker(checker CapacityChecker) {
+	s.capacity = checker
+}
+
+// SetDriverQualifier rejects assigning drivers to routes carrying dangerous
+// goods unless they hold the required certifications
+func (s *DeliveryTrackingService) SetDriverQualifier(detector HazmatDetector, qualifier DriverQualifier) {
+	s.hazmat = detector
+	s.drivers = qualifier
+}
+
 func (s *DeliveryTrackingService) CreateShipment(ctx context.Context, shipment deliverytypes.DeliveryShipment) (*deliverytypes.DeliveryShipment, error) {
 	// Validate the shipment
 	if err := s.validateShipment(shipment); err != nil {
@@ -48,6 +88,13 @@ func (s *DeliveryTrackingService) CreateShipment(ctx context.Context, shipment d
 	if shipment.Metadata == nil {
 		shipment.Metadata = make(map[string]interface{})
 	}
+	if shipment.RouteID != nil {
+		if err := s.checkCapacity(shipment.Metadata, func() ([]deliverytypes.CapacityViolation, error) {
+			return s.capacity.CheckRouteWithPicking(ctx, shipment.OrganizationID, *shipment.RouteID, shipment.PickingID)
+		}); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create the shipment
 	createdShipment, err := s.repo.CreateShipment(ctx, shipment)
@@ -96,6 +143,9 @@ func (s *DeliveryTrackingService) UpdateShipmentStatus(ctx context.Context, ship
 	if shipment == nil {
 		return nil, fmt.Errorf("shipment not found")
 	}
+	if !shipment.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: a %s shipment cannot move to %q", ErrInvalidShipmentStatus, shipment.Status, status)
+	}
 
 	// Update status
 	shipment.Status = status
@@ -229,6 +279,18 @@ func (s *DeliveryTrackingService) CreateRouteAssignment(ctx context.Context, ass
 	if assignment.Metadata == nil {
 		assignment.Metadata = make(map[string]interface{})
 	}
+	if assignment.VehicleID != nil {
+		if err := s.checkCapacity(assignment.Metadata, func() ([]deliverytypes.CapacityViolation, error) {
+			return s.capacity.CheckRouteWithVehicle(ctx, assignment.OrganizationID, assignment.RouteID, *assignment.VehicleID)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if assignment.DriverEmployeeID != nil {
+		if err := s.checkHazmatDriver(ctx, assignment.OrganizationID, assignment.RouteID, *assignment.DriverEmployeeID); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create the assignment
 	createdAssignment, err := s.repo.CreateRouteAssignment(ctx, assignment)
@@ -262,6 +324,13 @@ func (s *DeliveryTrackingService) CreateRouteStop(ctx context.Context, stop deli
 	if stop.Metadata == nil {
 		stop.Metadata = make(map[string]interface{})
 	}
+	if stop.ShipmentID != nil {
+		if err := s.checkCapacity(stop.Metadata, func() ([]deliverytypes.CapacityViolation, error) {
+			return s.capacity.CheckRouteWithShipment(ctx, stop.OrganizationID, stop.RouteID, *stop.ShipmentID)
+		}); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create the stop
 	createdStop, err := s.repo.CreateRouteStop(ctx, stop)
@@ -321,6 +390,46 @@ func (s *DeliveryTrackingService) UpdateRouteStopStatus(ctx context.Context, sto
 	return updatedStop, nil
 }
 
+// checkHazmatDriver fails when the route carries dangerous goods and the
+// driver lacks a required certification
+func (s *DeliveryTrackingService) checkHazmatDriver(ctx context.Context, orgID, routeID, employeeID uuid.UUID) error {
+	if s.hazmat == nil || s.drivers == nil {
+		return nil
+	}
+
+	hazmat, err := s.hazmat.IsHazmatRoute(ctx, orgID, routeID)
+	if err != nil {
+		return fmt.Errorf("failed to check route for dangerous goods: %w", err)
+	}
+	if !hazmat {
+		return nil
+	}
+	return s.drivers.RequireHazmatDriver(ctx, orgID, employeeID)
+}
+
+// checkCapacity runs a capacity check. Violations fail the change with a
+// *deliverytypes.CapacityError unless the metadata overrides them, in which
+// case they are recorded in the metadata.
+func (s *DeliveryTrackingService) checkCapacity(metadata map[string]interface{}, check func() ([]deliverytypes.CapacityViolation, error)) error {
+	if s.capacity == nil {
+		return nil
+	}
+
+	violations, err := check()
+	if err != nil {
+		return fmt.Errorf("failed to check vehicle capacity: %w", err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if override, _ := metadata[deliverytypes.CapacityOverrideKey].(bool); override {
+		metadata[deliverytypes.CapacityViolationsKey] = violations
+		return nil
+	}
+	return &deliverytypes.CapacityError{Violations: violations}
+}
+
 func (s *DeliveryTrackingService) validateShipment(shipment deliverytypes.DeliveryShipment) error {
 	if shipment.OrganizationID == uuid.Nil {
 		return fmt.Errorf("organization_id is required")
@@ -400,27 +509,23 @@ func (s *DeliveryTrackingService) publishShipmentEvent(ctx context.Context, even
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":                   shipment.ID,
-			"organization_id":      shipment.OrganizationID,
-			"picking_id":           shipment.PickingID,
-			"route_id":             shipment.RouteID,
-			"tracking_number":      shipment.TrackingNumber,
-			"carrier_name":         shipment.CarrierName,
-			"shipment_type":        shipment.ShipmentType,
-			"status":               shipment.Status,
-			"estimated_arrival_at": shipment.EstimatedArrivalAt,
-			"arrived_at":           shipment.ArrivedAt,
-			"metadata":             shipment.Metadata,
-			"created_at":           shipment.CreatedAt,
-			"updated_at":           shipment.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":                   shipment.ID,
+		"organization_id":      shipment.OrganizationID,
+		"picking_id":           shipment.PickingID,
+		"route_id":             shipment.RouteID,
+		"tracking_number":      shipment.TrackingNumber,
+		"carrier_name":         shipment.CarrierName,
+		"shipment_type":        shipment.ShipmentType,
+		"status":               shipment.Status,
+		"estimated_arrival_at": shipment.EstimatedArrivalAt,
+		"arrived_at":           shipment.ArrivedAt,
+		"metadata":             shipment.Metadata,
+		"created_at":           shipment.CreatedAt,
+		"updated_at":           shipment.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
 
 func (s *DeliveryTrackingService) publishTrackingEvent(ctx context.Context, eventType string, trackingEvent deliverytypes.DeliveryTrackingEvent) {
@@ -428,27 +533,23 @@ func (s *DeliveryTrackingService) publishTrackingEvent(ctx context.Context, even
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":              trackingEvent.ID,
-			"organization_id": trackingEvent.OrganizationID,
-			"shipment_id":     trackingEvent.ShipmentID,
-			"event_type":      trackingEvent.EventType,
-			"status":          trackingEvent.Status,
-			"event_time":      trackingEvent.EventTime,
-			"source":          trackingEvent.Source,
-			"message":         trackingEvent.Message,
-			"latitude":        trackingEvent.Latitude,
-			"longitude":       trackingEvent.Longitude,
-			"raw_payload":     trackingEvent.RawPayload,
-			"created_at":      trackingEvent.CreatedAt,
-			"updated_at":      trackingEvent.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":              trackingEvent.ID,
+		"organization_id": trackingEvent.OrganizationID,
+		"shipment_id":     trackingEvent.ShipmentID,
+		"event_type":      trackingEvent.EventType,
+		"status":          trackingEvent.Status,
+		"event_time":      trackingEvent.EventTime,
+		"source":          trackingEvent.Source,
+		"message":         trackingEvent.Message,
+		"latitude":        trackingEvent.Latitude,
+		"longitude":       trackingEvent.Longitude,
+		"raw_payload":     trackingEvent.RawPayload,
+		"created_at":      trackingEvent.CreatedAt,
+		"updated_at":      trackingEvent.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
 
 func (s *DeliveryTrackingService) publishRoutePositionEvent(ctx context.Context, eventType string, position deliverytypes.DeliveryRoutePosition) {
@@ -456,27 +557,23 @@ func (s *DeliveryTrackingService) publishRoutePositionEvent(ctx context.Context,
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":              position.ID,
-			"organization_id": position.OrganizationID,
-			"route_id":        position.RouteID,
-			"vehicle_id":      position.VehicleID,
-			"recorded_at":     position.RecordedAt,
-			"latitude":        position.Latitude,
-			"longitude":       position.Longitude,
-			"speed_kph":       position.SpeedKPH,
-			"heading":         position.Heading,
-			"source":          position.Source,
-			"metadata":        position.Metadata,
-			"created_at":      position.CreatedAt,
-			"updated_at":      position.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":              position.ID,
+		"organization_id": position.OrganizationID,
+		"route_id":        position.RouteID,
+		"vehicle_id":      position.VehicleID,
+		"recorded_at":     position.RecordedAt,
+		"latitude":        position.Latitude,
+		"longitude":       position.Longitude,
+		"speed_kph":       position.SpeedKPH,
+		"heading":         position.Heading,
+		"source":          position.Source,
+		"metadata":        position.Metadata,
+		"created_at":      position.CreatedAt,
+		"updated_at":      position.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
 
 func (s *DeliveryTrackingService) publishRouteAssignmentEvent(ctx context.Context, eventType string, assignment deliverytypes.DeliveryRouteAssignment) {
@@ -484,25 +581,21 @@ func (s *DeliveryTrackingService) publishRouteAssignmentEvent(ctx context.Contex
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":                 assignment.ID,
-			"organization_id":    assignment.OrganizationID,
-			"route_id":           assignment.RouteID,
-			"vehicle_id":         assignment.VehicleID,
-			"driver_employee_id": assignment.DriverEmployeeID,
-			"assignment_status":  assignment.AssignmentStatus,
-			"assigned_at":        assignment.AssignedAt,
-			"acknowledged_at":    assignment.AcknowledgedAt,
-			"metadata":           assignment.Metadata,
-			"created_at":         assignment.CreatedAt,
-			"updated_at":         assignment.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":                 assignment.ID,
+		"organization_id":    assignment.OrganizationID,
+		"route_id":           assignment.RouteID,
+		"vehicle_id":         assignment.VehicleID,
+		"driver_employee_id": assignment.DriverEmployeeID,
+		"assignment_status":  assignment.AssignmentStatus,
+		"assigned_at":        assignment.AssignedAt,
+		"acknowledged_at":    assignment.AcknowledgedAt,
+		"metadata":           assignment.Metadata,
+		"created_at":         assignment.CreatedAt,
+		"updated_at":         assignment.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
 
 func (s *DeliveryTrackingService) publishRouteStopEvent(ctx context.Context, eventType string, stop deliverytypes.DeliveryRouteStop) {
@@ -510,25 +603,21 @@ func (s *DeliveryTrackingService) publishRouteStopEvent(ctx context.Context, eve
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":                 stop.ID,
-			"organization_id":    stop.OrganizationID,
-			"route_id":           stop.RouteID,
-			"shipment_id":        stop.ShipmentID,
-			"stop_sequence":      stop.StopSequence,
-			"contact_id":         stop.ContactID,
-			"location_id":        stop.LocationID,
-			"status":             stop.Status,
-			"planned_arrival_at": stop.PlannedArrivalAt,
-			"actual_arrival_at":  stop.ActualArrivalAt,
-			"metadata":           stop.Metadata,
-			"created_at":         stop.CreatedAt,
-			"updated_at":         stop.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
+	eventData := map[string]interface{}{
+		"id":                 stop.ID,
+		"organization_id":    stop.OrganizationID,
+		"route_id":           stop.RouteID,
+		"shipment_id":        stop.ShipmentID,
+		"stop_sequence":      stop.StopSequence,
+		"contact_id":         stop.ContactID,
+		"location_id":        stop.LocationID,
+		"status":             stop.Status,
+		"planned_arrival_at": stop.PlannedArrivalAt,
+		"actual_arrival_at":  stop.ActualArrivalAt,
+		"metadata":           stop.Metadata,
+		"created_at":         stop.CreatedAt,
+		"updated_at":         stop.UpdatedAt,
 	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
@@ -164,22 +164,18 @@ func (s *DeliveryVehicleService) publishVehicleEvent(ctx context.Context, eventT
 		return
 	}
 
-	if eventBus, ok := s.eventBus.(interface {
-		Publish(eventType string, event interface{}) error
-	}); ok {
-		eventData := map[string]interface{}{
-			"id":                  vehicle.ID,
-			"organization_id":     vehicle.OrganizationID,
-			"name":                vehicle.Name,
-			"registration_number": vehicle.RegistrationNumber,
-			"vehicle_type":        vehicle.VehicleType,
-			"active":              vehicle.Active,
-			"capacity":            vehicle.Capacity,
-			"metadata":            vehicle.Metadata,
-			"created_at":          vehicle.CreatedAt,
-			"updated_at":          vehicle.UpdatedAt,
-		}
-
-		_ = eventBus.Publish(eventType, eventData)
-	}
+	eventData := map[string]interface{}{
+		"id":                  vehicle.ID,
+		"organization_id":     vehicle.OrganizationID,
+		"name":                vehicle.Name,
+		"registration_number": vehicle.RegistrationNumber,
+		"vehicle_type":        vehicle.VehicleType,
+		"active":              vehicle.Active,
+		"capacity":            vehicle.Capacity,
+		"metadata":            vehicle.Metadata,
+		"created_at":          vehicle.CreatedAt,
+		"updated_at":          vehicle.UpdatedAt,
+	}
+
+	_ = s.eventBus.Publish(ctx, eventType, eventData)
 }
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// TemperatureClass is the storage temperature goods need and a compartment provides
+type TemperatureClass string
+
+const (
+	TemperatureClassAmbient TemperatureClass = "ambient"
+	TemperatureClassChilled TemperatureClass = "chilled"
+	TemperatureClassFrozen  TemperatureClass = "frozen"
+)
+
+// IsValid reports whether the temperature class is supported
+func (c TemperatureClass) IsValid() bool {
+	switch c {
+	case TemperatureClassAmbient, TemperatureClassChilled, TemperatureClassFrozen:
+		return true
+	}
+	return false
+}
+
+// CapacityOverrideKey is the metadata flag that accepts a capacity violation
+// when adding a stop, shipment or vehicle to a route. The violations are
+// recorded next to it under CapacityViolationsKey.
+const (
+	CapacityOverrideKey   = "capacity_override"
+	CapacityViolationsKey = "capacity_violations"
+)
+
+// VehicleCompartment is a section of a vehicle with its own temperature and
+// limits. A nil limit is unlimited.
+type VehicleCompartment struct {
+	ID               uuid.UUID        `json:"id" db:"id"`
+	OrganizationID   uuid.UUID        `json:"organization_id" db:"organization_id"`
+	VehicleID        uuid.UUID        `json:"vehicle_id" db:"vehicle_id"`
+	Name             string           `json:"name" db:"name"`
+	TemperatureClass TemperatureClass `json:"temperature_class" db:"temperature_class"`
+	MaxWeight        *float64         `json:"max_weight" db:"max_weight"`
+	MaxVolume        *float64         `json:"max_volume" db:"max_volume"`
+}
+
+// VehicleCapacity is what a vehicle can carry. A vehicle without
+// compartments is one ambient compartment bounded by the vehicle limits.
+type VehicleCapacity struct {
+	VehicleID    uuid.UUID            `json:"vehicle_id"`
+	VehicleName  string               `json:"vehicle_name"`
+	MaxWeight    *float64             `json:"max_weight"`
+	MaxVolume    *float64             `json:"max_volume"`
+	Compartments []VehicleCompartment `json:"compartments"`
+}
+
+// ShipmentLoad is the weight and volume a shipment puts in one temperature
+// class. ShipmentID is nil for a shipment that is not created yet.
+type ShipmentLoad struct {
+	ShipmentID       *uuid.UUID       `json:"shipment_id,omitempty"`
+	TemperatureClass TemperatureClass `json:"temperature_class"`
+	Weight           float64          `json:"weight"`
+	Volume           float64          `json:"volume"`
+	// Unmeasured counts product lines without a weight or volume
+	Unmeasured int `json:"unmeasured"`
+}
+
+// CapacityUsage compares the load of a temperature class, or of the whole
+// vehicle when TemperatureClass is empty, with its limits
+type CapacityUsage struct {
+	TemperatureClass TemperatureClass `json:"temperature_class,omitempty"`
+	Weight           float64          `json:"weight"`
+	Volume           float64          `json:"volume"`
+	MaxWeight        *float64         `json:"max_weight"`
+	MaxVolume        *float64         `json:"max_volume"`
+}
+
+// CapacityViolationCode identifies what is exceeded
+type CapacityViolationCode string
+
+const (
+	CapacityViolationVehicleWeight     CapacityViolationCode = "vehicle_weight_exceeded"
+	CapacityViolationVehicleVolume     CapacityViolationCode = "vehicle_volume_exceeded"
+	CapacityViolationCompartmentWeight CapacityViolationCode = "compartment_weight_exceeded"
+	CapacityViolationCompartmentVolume CapacityViolationCode = "compartment_volume_exceeded"
+	CapacityViolationNoCompartment     CapacityViolationCode = "no_compartment"
+)
+
+// CapacityViolation is one way a route overloads its vehicle, with the
+// shipments contributing most to it so planners know what to move
+type CapacityViolation struct {
+	Code             CapacityViolationCode `json:"code"`
+	TemperatureClass TemperatureClass      `json:"temperature_class,omitempty"`
+	Limit            float64               `json:"limit"`
+	Load             float64               `json:"load"`
+	Excess           float64               `json:"excess"`
+	Message          string                `json:"message"`
+	Suggestion       string                `json:"suggestion"`
+	ShipmentIDs      []uuid.UUID           `json:"shipment_ids,omitempty"`
+}
+
+// CapacityReport is the load of a route against its assigned vehicle
+type CapacityReport struct {
+	RouteID    uuid.UUID           `json:"route_id"`
+	Vehicle    *VehicleCapacity    `json:"vehicle"`
+	Usage      []CapacityUsage     `json:"usage"`
+	Violations []CapacityViolation `json:"violations"`
+	// Unmeasured counts product lines left out of the totals for lacking a weight or volume
+	Unmeasured int `json:"unmeasured"`
+}
+
+// CapacityError is returned when a change would overload a route's vehicle
+type CapacityError struct {
+	Violations []CapacityViolation `json:"violations"`
+}
+
+func (e *CapacityError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return fmt.Sprintf("vehicle capacity exceeded: %s", strings.Join(messages, "; "))
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsolidationMatch is why shipments are suggested together
+type ConsolidationMatch string
+
+const (
+	// ConsolidationMatchAddress merges shipments to the same address into
+	// one shipment delivered at one stop
+	ConsolidationMatchAddress ConsolidationMatch = "address"
+	// ConsolidationMatchZone puts shipments to the same postal zone on one
+	// route so they are delivered in one trip
+	ConsolidationMatchZone ConsolidationMatch = "zone"
+)
+
+// IsValid reports whether the match is supported
+func (m ConsolidationMatch) IsValid() bool {
+	return m == ConsolidationMatchAddress || m == ConsolidationMatchZone
+}
+
+// ConsolidationSettings are stored under the "delivery_consolidation" key of
+// the organization's settings. Costs are in the organization's currency and
+// only drive the projected savings.
+type ConsolidationSettings struct {
+	// WindowHours is how far apart the planned dates of consolidated shipments may be
+	WindowHours int `json:"window_hours"`
+	// MatchZones also suggests grouping shipments to different addresses of a zone
+	MatchZones bool `json:"match_zones"`
+	// ZonePrefixLength is how many leading postal code characters make a zone
+	ZonePrefixLength int `json:"zone_prefix_length"`
+	// AutoApply merges same-address shipments without waiting for a planner.
+	// Zone suggestions are never applied automatically.
+	AutoApply bool `json:"auto_apply"`
+	// TripCost is the cost of a separate delivery run
+	TripCost float64 `json:"trip_cost"`
+	// StopCost is the cost of one stop on a route
+	StopCost float64 `json:"stop_cost"`
+	// ShipmentCost is the handling and labelling cost of one shipment
+	ShipmentCost float64 `json:"shipment_cost"`
+}
+
+// ConsolidationCandidate is a pending outbound shipment with its destination
+type ConsolidationCandidate struct {
+	ShipmentID     uuid.UUID  `json:"shipment_id"`
+	RouteID        *uuid.UUID `json:"route_id,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	PartnerID      *uuid.UUID `json:"partner_id,omitempty"`
+	Street         string     `json:"street,omitempty"`
+	Zip            string     `json:"zip,omitempty"`
+	City           string     `json:"city,omitempty"`
+	CountryID      *uuid.UUID `json:"country_id,omitempty"`
+	PlannedAt      time.Time  `json:"planned_at"`
+	Weight         float64    `json:"weight"`
+	Volume         float64    `json:"volume"`
+}
+
+// ConsolidationSavings breaks projected savings down by what is saved
+type ConsolidationSavings struct {
+	TripsSaved     int     `json:"trips_saved"`
+	StopsSaved     int     `json:"stops_saved"`
+	ShipmentsSaved int     `json:"shipments_saved"`
+	Total          float64 `json:"total"`
+}
+
+// ConsolidationSuggestion is a group of shipments worth delivering together.
+// The primary shipment keeps its route and stop; the others join it.
+type ConsolidationSuggestion struct {
+	Match             ConsolidationMatch       `json:"match"`
+	Destination       string                   `json:"destination"`
+	PrimaryShipmentID uuid.UUID                `json:"primary_shipment_id"`
+	ShipmentIDs       []uuid.UUID              `json:"shipment_ids"`
+	Shipments         []ConsolidationCandidate `json:"shipments"`
+	WindowStart       time.Time                `json:"window_start"`
+	WindowEnd         time.Time                `json:"window_end"`
+	Weight            float64                  `json:"weight"`
+	Volume            float64                  `json:"volume"`
+	Savings           ConsolidationSavings     `json:"savings"`
+	// Applicable is false when the suggestion cannot be applied as is; Reason says why
+	Applicable bool   `json:"applicable"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// ConsolidationRequest applies a suggestion
+type ConsolidationRequest struct {
+	Match       ConsolidationMatch `json:"match"`
+	ShipmentIDs []uuid.UUID        `json:"shipment_ids"`
+}
+
+// Consolidation records an applied suggestion
+type Consolidation struct {
+	ID                uuid.UUID            `json:"id" db:"id"`
+	OrganizationID    uuid.UUID            `json:"organization_id" db:"organization_id"`
+	Match             ConsolidationMatch   `json:"match" db:"match_type"`
+	PrimaryShipmentID uuid.UUID            `json:"primary_shipment_id" db:"primary_shipment_id"`
+	ShipmentIDs       []uuid.UUID          `json:"shipment_ids" db:"shipment_ids"`
+	RouteID           *uuid.UUID           `json:"route_id,omitempty" db:"route_id"`
+	Savings           ConsolidationSavings `json:"savings" db:"savings"`
+	Automatic         bool                 `json:"automatic" db:"automatic"`
+	AppliedBy         *uuid.UUID           `json:"applied_by,omitempty" db:"applied_by"`
+	AppliedAt         time.Time            `json:"applied_at" db:"applied_at"`
+}
@@ -0,0 +1,166 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metadata keys read by emission estimates. DistanceKmKey holds the planned
+// distance of a route, or the carrier's distance for a shipment;
+// VehicleEmissionKey overrides the factor of a vehicle's type.
+const (
+	DistanceKmKey      = "distance_km"
+	VehicleEmissionKey = "co2e_kg_per_km"
+)
+
+// EmissionFactors are stored under the "delivery_emission_factors" key of
+// the organization's settings. Factors missing there fall back to defaults.
+type EmissionFactors struct {
+	// VehicleKgPerKm is the CO2e an own vehicle emits per kilometre, by vehicle type
+	VehicleKgPerKm map[string]float64 `json:"vehicle_kg_per_km"`
+	// CarrierKgPerTonneKm is the CO2e per tonne-kilometre shipped with a carrier, by carrier code
+	CarrierKgPerTonneKm map[string]float64 `json:"carrier_kg_per_tonne_km"`
+	// DefaultCarrierKgPerTonneKm applies to carriers without a factor of their own
+	DefaultCarrierKgPerTonneKm float64 `json:"default_carrier_kg_per_tonne_km"`
+}
+
+// EmissionMethod is how a shipment's emissions were estimated
+type EmissionMethod string
+
+const (
+	// EmissionMethodFleet shares a route's emissions between its shipments by weight
+	EmissionMethodFleet EmissionMethod = "fleet"
+	// EmissionMethodCarrier multiplies weight, distance and the carrier factor
+	EmissionMethodCarrier EmissionMethod = "carrier"
+)
+
+// DistanceSource is where a route's distance comes from
+type DistanceSource string
+
+const (
+	DistanceSourceGPS     DistanceSource = "gps"
+	DistanceSourcePlanned DistanceSource = "planned"
+	DistanceSourceNone    DistanceSource = "none"
+)
+
+// EmissionPeriod groups a sustainability report
+type EmissionPeriod string
+
+const (
+	EmissionPeriodDay   EmissionPeriod = "day"
+	EmissionPeriodWeek  EmissionPeriod = "week"
+	EmissionPeriodMonth EmissionPeriod = "month"
+)
+
+// IsValid reports whether the period is supported
+func (p EmissionPeriod) IsValid() bool {
+	switch p {
+	case EmissionPeriodDay, EmissionPeriodWeek, EmissionPeriodMonth:
+		return true
+	}
+	return false
+}
+
+// EmissionTrip is an own-fleet route with what its emissions depend on
+type EmissionTrip struct {
+	RouteID           uuid.UUID          `json:"route_id"`
+	RouteCode         string             `json:"route_code,omitempty"`
+	RouteName         string             `json:"route_name"`
+	VehicleType       string             `json:"vehicle_type,omitempty"`
+	VehicleKgPerKm    *float64           `json:"vehicle_kg_per_km,omitempty"`
+	GPSDistanceKm     float64            `json:"gps_distance_km"`
+	PlannedDistanceKm *float64           `json:"planned_distance_km,omitempty"`
+	Date              time.Time          `json:"date"`
+	Shipments         []EmissionShipment `json:"shipments"`
+}
+
+// EmissionShipment is a shipment with what its emissions depend on
+type EmissionShipment struct {
+	ShipmentID     uuid.UUID  `json:"shipment_id"`
+	RouteID        *uuid.UUID `json:"route_id,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	CustomerID     *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerName   string     `json:"customer_name,omitempty"`
+	CarrierCode    string     `json:"carrier_code,omitempty"`
+	WeightKg       float64    `json:"weight_kg"`
+	DistanceKm     *float64   `json:"distance_km,omitempty"`
+	Date           time.Time  `json:"date"`
+}
+
+// ShipmentEmission is the estimated CO2e of one shipment. Estimated is false
+// when the distance or weight needed is unknown; Reason says which.
+type ShipmentEmission struct {
+	ShipmentID     uuid.UUID      `json:"shipment_id"`
+	RouteID        *uuid.UUID     `json:"route_id,omitempty"`
+	TrackingNumber string         `json:"tracking_number,omitempty"`
+	CustomerID     *uuid.UUID     `json:"customer_id,omitempty"`
+	CustomerName   string         `json:"customer_name,omitempty"`
+	CarrierCode    string         `json:"carrier_code,omitempty"`
+	Method         EmissionMethod `json:"method"`
+	WeightKg       float64        `json:"weight_kg"`
+	DistanceKm     float64        `json:"distance_km"`
+	KgCO2e         float64        `json:"kg_co2e"`
+	Date           time.Time      `json:"date"`
+	Estimated      bool           `json:"estimated"`
+	Reason         string         `json:"reason,omitempty"`
+}
+
+// RouteEmission is the estimated CO2e of a route and its share per shipment
+type RouteEmission struct {
+	RouteID        uuid.UUID          `json:"route_id"`
+	RouteCode      string             `json:"route_code,omitempty"`
+	RouteName      string             `json:"route_name"`
+	VehicleType    string             `json:"vehicle_type,omitempty"`
+	DistanceKm     float64            `json:"distance_km"`
+	DistanceSource DistanceSource     `json:"distance_source"`
+	KgPerKm        float64            `json:"kg_per_km"`
+	KgCO2e         float64            `json:"kg_co2e"`
+	Estimated      bool               `json:"estimated"`
+	Reason         string             `json:"reason,omitempty"`
+	Date           time.Time          `json:"date"`
+	Shipments      []ShipmentEmission `json:"shipments"`
+}
+
+// SustainabilityReportFilter selects the deliveries of a report
+type SustainabilityReportFilter struct {
+	OrganizationID uuid.UUID
+	From           time.Time
+	To             time.Time
+	Period         EmissionPeriod
+	CustomerID     *uuid.UUID
+}
+
+// EmissionPeriodTotal is the CO2e of one period of a report
+type EmissionPeriodTotal struct {
+	PeriodStart time.Time `json:"period_start"`
+	KgCO2e      float64   `json:"kg_co2e"`
+	Shipments   int       `json:"shipments"`
+}
+
+// EmissionCustomerTotal is the CO2e of one customer's shipments
+type EmissionCustomerTotal struct {
+	CustomerID   *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerName string     `json:"customer_name"`
+	KgCO2e       float64    `json:"kg_co2e"`
+	Shipments    int        `json:"shipments"`
+	WeightKg     float64    `json:"weight_kg"`
+}
+
+// SustainabilityReport totals delivery emissions of a date range. Emissions
+// of routes without shipments count in the totals but not per customer.
+// FleetDistanceKm is the distance driven by the organization's own vehicles.
+type SustainabilityReport struct {
+	From                 time.Time                  `json:"from"`
+	To                   time.Time                  `json:"to"`
+	Period               EmissionPeriod             `json:"period"`
+	CustomerID           *uuid.UUID                 `json:"customer_id,omitempty"`
+	KgCO2e               float64                    `json:"kg_co2e"`
+	FleetDistanceKm      float64                    `json:"fleet_distance_km"`
+	Routes               int                        `json:"routes"`
+	Shipments            int                        `json:"shipments"`
+	UnestimatedShipments int                        `json:"unestimated_shipments"`
+	ByPeriod             []EmissionPeriodTotal      `json:"by_period"`
+	ByCustomer           []EmissionCustomerTotal    `json:"by_customer"`
+	ByMethod             map[EmissionMethod]float64 `json:"by_method"`
+}
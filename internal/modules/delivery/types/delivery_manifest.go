@@ -0,0 +1,87 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RouteManifest is the printable summary of a route: its stops in delivery
+// sequence with what is dropped at each, and the order to load the vehicle.
+type RouteManifest struct {
+	RouteID          uuid.UUID         `json:"route_id"`
+	OrganizationID   uuid.UUID         `json:"organization_id"`
+	RouteName        string            `json:"route_name"`
+	RouteCode        string            `json:"route_code"`
+	Status           RouteStatus       `json:"status"`
+	ScheduledStartAt *time.Time        `json:"scheduled_start_at"`
+	Vehicle          *ManifestVehicle  `json:"vehicle,omitempty"`
+	Stops            []ManifestStop    `json:"stops"`
+	LoadingOrder     []ManifestLoadRow `json:"loading_order"`
+	TotalItems       int               `json:"total_items"`
+	TotalWeight      float64           `json:"total_weight"`
+	TotalVolume      float64           `json:"total_volume"`
+	HazmatStops      int               `json:"hazmat_stops"`
+	Warnings         []string          `json:"warnings,omitempty"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+}
+
+// ManifestVehicle is the vehicle of the route's current assignment
+type ManifestVehicle struct {
+	ID                 uuid.UUID   `json:"id"`
+	Name               string      `json:"name"`
+	RegistrationNumber string      `json:"registration_number"`
+	VehicleType        VehicleType `json:"vehicle_type"`
+	Capacity           float64     `json:"capacity"`
+}
+
+// ManifestStop is one stop of the manifest with the items delivered there
+type ManifestStop struct {
+	StopID            uuid.UUID              `json:"stop_id"`
+	Sequence          int                    `json:"sequence"`
+	Status            StopStatus             `json:"status"`
+	ContactName       string                 `json:"contact_name,omitempty"`
+	Address           map[string]interface{} `json:"address,omitempty"`
+	PlannedArrivalAt  *time.Time             `json:"planned_arrival_at"`
+	ShipmentID        *uuid.UUID             `json:"shipment_id,omitempty"`
+	TrackingNumber    string                 `json:"tracking_number,omitempty"`
+	PickingName       string                 `json:"picking_name,omitempty"`
+	RequiresSignature bool                   `json:"requires_signature"`
+	Notes             string                 `json:"notes,omitempty"`
+	Items             []ManifestItem         `json:"items"`
+	Weight            float64                `json:"weight"`
+	Volume            float64                `json:"volume"`
+	Hazmat            bool                   `json:"hazmat"`
+}
+
+// ManifestItem is a product line of a stop's shipment. Weight and volume
+// are the line totals; they are nil when the product has no measurements.
+type ManifestItem struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	ProductCode string    `json:"product_code,omitempty"`
+	Quantity    float64   `json:"quantity"`
+	Weight      *float64  `json:"weight"`
+	Volume      *float64  `json:"volume"`
+	HazmatClass string    `json:"hazmat_class,omitempty"`
+	UNNumber    string    `json:"un_number,omitempty"`
+	HazmatNotes string    `json:"hazmat_notes,omitempty"`
+}
+
+// IsHazmat reports whether the item is dangerous goods
+func (i ManifestItem) IsHazmat() bool {
+	return i.HazmatClass != "" || i.UNNumber != ""
+}
+
+// ManifestLoadRow is one step of the loading list. Position 1 is loaded
+// first and so ends up deepest in the vehicle.
+type ManifestLoadRow struct {
+	Position       int       `json:"position"`
+	StopID         uuid.UUID `json:"stop_id"`
+	StopSequence   int       `json:"stop_sequence"`
+	TrackingNumber string    `json:"tracking_number,omitempty"`
+	Items          int       `json:"items"`
+	Weight         float64   `json:"weight"`
+	Volume         float64   `json:"volume"`
+	Hazmat         bool      `json:"hazmat"`
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageSenderRole tells whether a route message comes from the driver or a dispatcher
+type MessageSenderRole string
+
+const (
+	MessageSenderDriver     MessageSenderRole = "driver"
+	MessageSenderDispatcher MessageSenderRole = "dispatcher"
+)
+
+// Realtime event types of a route's message stream
+const (
+	MessageEventCreated = "message"
+	MessageEventRead    = "read"
+)
+
+// DeliveryMessage is a message between dispatchers and the driver of a
+// route, optionally about one of its stops. Photos are attachments.
+type DeliveryMessage struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id" db:"organization_id"`
+	RouteID        uuid.UUID         `json:"route_id" db:"route_id"`
+	StopID         *uuid.UUID        `json:"stop_id,omitempty" db:"stop_id"`
+	SenderID       uuid.UUID         `json:"sender_id" db:"sender_id"`
+	SenderRole     MessageSenderRole `json:"sender_role" db:"sender_role"`
+	Body           string            `json:"body" db:"body"`
+	AttachmentID   *uuid.UUID        `json:"attachment_id,omitempty" db:"attachment_id"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	Reads          []MessageRead     `json:"reads"`
+}
+
+// MessageRead is a read receipt
+type MessageRead struct {
+	MessageID uuid.UUID `json:"message_id" db:"message_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	ReadAt    time.Time `json:"read_at" db:"read_at"`
+}
+
+// DeliveryMessageRequest posts a message to a route. A message needs a body,
+// a photo, or both; the photo is uploaded as an attachment first.
+type DeliveryMessageRequest struct {
+	StopID       *uuid.UUID `json:"stop_id,omitempty"`
+	Body         string     `json:"body"`
+	AttachmentID *uuid.UUID `json:"attachment_id,omitempty"`
+}
+
+// DeliveryMessageFilter pages through a route's messages, newest first
+type DeliveryMessageFilter struct {
+	OrganizationID uuid.UUID
+	RouteID        uuid.UUID
+	StopID         *uuid.UUID
+	Before         *time.Time
+	Limit          int
+}
+
+// MessageReadRequest marks messages as read. Without message IDs every
+// message of the route the user has not read yet is marked.
+type MessageReadRequest struct {
+	MessageIDs []uuid.UUID `json:"message_ids,omitempty"`
+}
+
+// MessageReadReceipt is streamed when a user reads messages of a route
+type MessageReadReceipt struct {
+	RouteID    uuid.UUID   `json:"route_id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	MessageIDs []uuid.UUID `json:"message_ids"`
+	ReadAt     time.Time   `json:"read_at"`
+}
+
+// RouteParticipants are the users a route's messages are pushed to
+type RouteParticipants struct {
+	// DriverUserIDs are the users of the drivers of the route's open assignments
+	DriverUserIDs []uuid.UUID
+	// DispatcherUserIDs are the route's creator and the users who wrote on it
+	// without being its driver
+	DispatcherUserIDs []uuid.UUID
+}
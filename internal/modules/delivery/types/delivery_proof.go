@@ -0,0 +1,44 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryProof is the printable proof of delivery of a shipment: who
+// received what, where and when, as recorded by its delivered tracking event
+type DeliveryProof struct {
+	ShipmentID        uuid.UUID              `json:"shipment_id"`
+	OrganizationID    uuid.UUID              `json:"organization_id"`
+	TrackingNumber    string                 `json:"tracking_number,omitempty"`
+	CarrierName       string                 `json:"carrier_name,omitempty"`
+	Status            ShipmentStatus         `json:"status"`
+	PickingID         uuid.UUID              `json:"picking_id"`
+	PickingName       string                 `json:"picking_name,omitempty"`
+	RecipientName     string                 `json:"recipient_name,omitempty"`
+	Address           map[string]interface{} `json:"address,omitempty"`
+	RouteID           *uuid.UUID             `json:"route_id,omitempty"`
+	RouteName         string                 `json:"route_name,omitempty"`
+	RouteCode         string                 `json:"route_code,omitempty"`
+	StopSequence      *int                   `json:"stop_sequence,omitempty"`
+	StopNotes         string                 `json:"stop_notes,omitempty"`
+	RequiresSignature bool                   `json:"requires_signature"`
+	DeliveredAt       *time.Time             `json:"delivered_at"`
+	DeliveryMessage   string                 `json:"delivery_message,omitempty"`
+	// DeliveryDetails is the payload of the delivered event, e.g. the
+	// signatory's name captured by the driver's app
+	DeliveryDetails map[string]interface{} `json:"delivery_details,omitempty"`
+	Latitude        *float64               `json:"latitude"`
+	Longitude       *float64               `json:"longitude"`
+	Items           []ManifestItem         `json:"items"`
+	GeneratedAt     time.Time              `json:"generated_at"`
+}
+
+// DeliveredShipment identifies a delivered shipment when selecting the
+// proofs of delivery to print
+type DeliveredShipment struct {
+	ShipmentID     uuid.UUID
+	TrackingNumber string
+	PickingName    string
+}
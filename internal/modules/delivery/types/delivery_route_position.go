@@ -22,3 +22,4 @@ type DeliveryRoutePosition struct {
 	Metadata     map[string]interface{} `json:"metadata" db:"metadata"`
 	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time        `json:"updated_at" db:"updated_at"`
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+
 	"github.com/google/uuid"
 )
 
@@ -17,6 +19,28 @@ const (
 	ShipmentStatusCancelled ShipmentStatus = "cancelled"
 )
 
+// ShipmentStatusEnum lists the shipment statuses and the statuses each may
+// move to. Delivered and cancelled shipments are final; failed ones may be
+// rescheduled or cancelled.
+var ShipmentStatusEnum = enums.Enum{Name: "delivery.shipment_status", Values: []enums.Value{
+	{Value: string(ShipmentStatusDraft), Labels: enums.Labels{"en": "Draft", "fr": "Brouillon", "es": "Borrador"},
+		Transitions: []string{string(ShipmentStatusScheduled), string(ShipmentStatusInTransit), string(ShipmentStatusCancelled)}},
+	{Value: string(ShipmentStatusScheduled), Labels: enums.Labels{"en": "Scheduled", "fr": "Planifiée", "es": "Programado"},
+		Transitions: []string{string(ShipmentStatusInTransit), string(ShipmentStatusDelivered), string(ShipmentStatusFailed), string(ShipmentStatusCancelled)}},
+	{Value: string(ShipmentStatusInTransit), Labels: enums.Labels{"en": "In transit", "fr": "En transit", "es": "En tránsito"},
+		Transitions: []string{string(ShipmentStatusDelivered), string(ShipmentStatusFailed)}},
+	{Value: string(ShipmentStatusDelivered), Labels: enums.Labels{"en": "Delivered", "fr": "Livrée", "es": "Entregado"}},
+	{Value: string(ShipmentStatusFailed), Labels: enums.Labels{"en": "Failed", "fr": "Échouée", "es": "Fallido"},
+		Transitions: []string{string(ShipmentStatusScheduled), string(ShipmentStatusInTransit), string(ShipmentStatusCancelled)}},
+	{Value: string(ShipmentStatusCancelled), Labels: enums.Labels{"en": "Cancelled", "fr": "Annulée", "es": "Cancelado"}},
+}}
+
+// CanTransitionTo reports whether a shipment may move from the status to
+// another
+func (s ShipmentStatus) CanTransitionTo(next ShipmentStatus) bool {
+	return ShipmentStatusEnum.CanTransition(string(s), string(next))
+}
+
 type ShipmentType string
 
 const (
@@ -25,6 +49,13 @@ const (
 	ShipmentTypeInternal ShipmentType = "internal"
 )
 
+// ShipmentTypeEnum lists the shipment types to clients
+var ShipmentTypeEnum = enums.Enum{Name: "delivery.shipment_type", Values: []enums.Value{
+	{Value: string(ShipmentTypeOutbound), Labels: enums.Labels{"en": "Outbound", "fr": "Sortante", "es": "Saliente"}},
+	{Value: string(ShipmentTypeInbound), Labels: enums.Labels{"en": "Inbound", "fr": "Entrante", "es": "Entrante"}},
+	{Value: string(ShipmentTypeInternal), Labels: enums.Labels{"en": "Internal", "fr": "Interne", "es": "Interno"}},
+}}
+
 type DeliveryShipment struct {
 	ID                  uuid.UUID      `json:"id" db:"id"`
 	OrganizationID      uuid.UUID      `json:"organization_id" db:"organization_id"`
@@ -0,0 +1,423 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxRequestBody bounds JSON request bodies; batches carry their data inline
+const maxRequestBody = 8 << 20
+
+// DocumentHandler handles document templates, branding and generation
+type DocumentHandler struct {
+	service *service.DocumentService
+}
+
+func NewDocumentHandler(service *service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{service: service}
+}
+
+func (h *DocumentHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/document-branding", h.GetBranding)
+	router.PUT("/api/v1/document-branding", h.UpdateBranding)
+
+	router.GET("/api/v1/document-templates", h.ListTemplates)
+	router.POST("/api/v1/document-templates", h.CreateTemplate)
+	router.GET("/api/v1/document-templates/:code", h.GetTemplate)
+	router.GET("/api/v1/document-templates/:code/versions", h.ListTemplateVersions)
+	router.POST("/api/v1/document-templates/:code/versions", h.SaveTemplateVersion)
+	router.POST("/api/v1/document-templates/:code/versions/:version/activate", h.ActivateTemplateVersion)
+	router.POST("/api/v1/document-templates/:code/preview", h.Preview)
+
+	router.POST("/api/v1/documents", h.Generate)
+	router.GET("/api/v1/documents/:id", h.GetDocument)
+	router.GET("/api/v1/documents/:id/download", h.Download)
+
+	router.POST("/api/v1/document-batches", h.CreateBatch)
+	router.GET("/api/v1/document-batches/:id", h.GetBatch)
+	router.GET("/api/v1/document-batches/:id/documents", h.ListBatchDocuments)
+	router.GET(service.BatchArchivePath, h.DownloadBatchArchive)
+}
+
+// GetBranding handles GET /api/v1/document-branding
+func (h *DocumentHandler) GetBranding(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	branding, err := h.service.GetBranding(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, branding)
+}
+
+// UpdateBranding handles PUT /api/v1/document-branding
+func (h *DocumentHandler) UpdateBranding(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.Branding
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	branding, err := h.service.UpdateBranding(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, branding)
+}
+
+// ListTemplates handles GET /api/v1/document-templates
+func (h *DocumentHandler) ListTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	templates, err := h.service.ListTemplates(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// CreateTemplate handles POST /api/v1/document-templates
+func (h *DocumentHandler) CreateTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.TemplateCreateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tmpl, err := h.service.CreateTemplate(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tmpl)
+}
+
+// GetTemplate handles GET /api/v1/document-templates/:code
+func (h *DocumentHandler) GetTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	tmpl, err := h.service.GetTemplate(r.Context(), authCtx.OrganizationID, ps.ByName("code"))
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+// ListTemplateVersions handles GET /api/v1/document-templates/:code/versions
+func (h *DocumentHandler) ListTemplateVersions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListTemplateVersions(r.Context(), authCtx.OrganizationID, ps.ByName("code"))
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// SaveTemplateVersion handles POST /api/v1/document-templates/:code/versions
+func (h *DocumentHandler) SaveTemplateVersion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.TemplateVersionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	version, err := h.service.SaveTemplateVersion(r.Context(), authCtx.OrganizationID, authCtx.UserID, ps.ByName("code"), req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, version)
+}
+
+// ActivateTemplateVersion handles POST /api/v1/document-templates/:code/versions/:version/activate
+func (h *DocumentHandler) ActivateTemplateVersion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(ps.ByName("version"))
+	if err != nil || version < 1 {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := h.service.ActivateTemplateVersion(r.Context(), authCtx.OrganizationID, ps.ByName("code"), version)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+// Preview handles POST /api/v1/document-templates/:code/preview. It returns
+// the rendered HTML, or the PDF when format is "pdf", without storing it.
+func (h *DocumentHandler) Preview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PreviewRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	body, contentType, err := h.service.Preview(r.Context(), authCtx.OrganizationID, ps.ByName("code"), req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	// Templates are user-authored; keep previews from running scripts in the app's origin
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// Generate handles POST /api/v1/documents
+func (h *DocumentHandler) Generate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.GenerateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	doc, err := h.service.Generate(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, doc)
+}
+
+// GetDocument handles GET /api/v1/documents/:id
+func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	doc, err := h.service.GetDocument(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// Download handles GET /api/v1/documents/:id/download
+func (h *DocumentHandler) Download(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	doc, err := h.service.GetDocument(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc.Content)))
+	w.Header().Set("ETag", `"`+doc.SHA256+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc.Content)
+}
+
+// CreateBatch handles POST /api/v1/document-batches. The batch is generated
+// in the background; poll GET /api/v1/document-batches/:id for progress.
+func (h *DocumentHandler) CreateBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.BatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	batch, err := h.service.CreateBatch(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/document-batches/"+batch.ID.String())
+	writeJSON(w, http.StatusAccepted, batch)
+}
+
+// GetBatch handles GET /api/v1/document-batches/:id
+func (h *DocumentHandler) GetBatch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.service.GetBatch(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, batch)
+}
+
+// ListBatchDocuments handles GET /api/v1/document-batches/:id/documents
+func (h *DocumentHandler) ListBatchDocuments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	docs, err := h.service.ListBatchDocuments(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docs)
+}
+
+// DownloadBatchArchive handles GET /api/v1/document-batches/:id/archive,
+// streaming the zip of a packaged batch until its archive expires
+func (h *DocumentHandler) DownloadBatchArchive(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	batch, archive, err := h.service.OpenArchive(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "documents-"+batch.ID.String()+".zip"))
+	w.Header().Set("Content-Length", strconv.FormatInt(batch.Archive.Size, 10))
+	w.Header().Set("ETag", `"`+batch.Archive.SHA256+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, archive)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeDocumentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrArchiveExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, service.ErrRendererUnavailable), errors.Is(err, service.ErrStorageUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/service"
+)
+
+// BatchRunner generates queued document batches in the background. Batches
+// are claimed with row locks, so every instance can run one.
+type BatchRunner struct {
+	documentService *service.DocumentService
+	interval        time.Duration
+	logger          *slog.Logger
+}
+
+func NewBatchRunner(documentService *service.DocumentService, interval time.Duration, logger *slog.Logger) *BatchRunner {
+	return &BatchRunner{
+		documentService: documentService,
+		interval:        interval,
+		logger:          logger,
+	}
+}
+
+// Start polls for queued batches every interval until ctx is cancelled
+func (r *BatchRunner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.drain(ctx)
+				r.expireArchives(ctx)
+			}
+		}
+	}()
+}
+
+// drain processes batches until none are queued
+func (r *BatchRunner) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := r.documentService.ProcessNextBatch(ctx)
+		if err != nil {
+			r.logger.Error("Document batch processing failed", "error", err)
+			return
+		}
+		if !processed {
+			return
+		}
+	}
+}
+
+// expireArchives deletes the batch archives past their retention
+func (r *BatchRunner) expireArchives(ctx context.Context) {
+	expired, err := r.documentService.ExpireArchives(ctx)
+	if err != nil {
+		r.logger.Error("Document batch archive expiry failed", "error", err)
+	}
+	if expired > 0 {
+		r.logger.Info("Document batch archives expired", "count", expired)
+	}
+}
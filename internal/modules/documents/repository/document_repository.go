@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when a template, version, document or batch does not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// DocumentRepo defines the interface for document repository operations
+type DocumentRepo interface {
+	GetBranding(ctx context.Context, orgID uuid.UUID) (*types.Branding, error)
+	SaveBranding(ctx context.Context, branding types.Branding) (*types.Branding, error)
+
+	ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.Template, error)
+	FindTemplate(ctx context.Context, orgID uuid.UUID, code string) (*types.Template, error)
+	FindTemplateByID(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error)
+	CreateTemplate(ctx context.Context, tmpl types.Template, version types.TemplateVersion) (*types.Template, *types.TemplateVersion, error)
+	AddVersion(ctx context.Context, version types.TemplateVersion, activate bool) (*types.TemplateVersion, error)
+	ListVersions(ctx context.Context, orgID, templateID uuid.UUID) ([]types.TemplateVersion, error)
+	FindVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) (*types.TemplateVersion, error)
+	ActivateVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) error
+
+	SaveDocument(ctx context.Context, doc types.Document) (*types.Document, error)
+	FindDocument(ctx context.Context, orgID, id uuid.UUID) (*types.Document, error)
+	ListBatchDocuments(ctx context.Context, orgID, batchID uuid.UUID) ([]types.Document, error)
+
+	CreateBatch(ctx context.Context, batch types.Batch) (*types.Batch, error)
+	FindBatch(ctx context.Context, orgID, id uuid.UUID) (*types.Batch, error)
+	ClaimBatch(ctx context.Context, staleBefore time.Time) (*types.Batch, error)
+	UpdateBatchProgress(ctx context.Context, batch types.Batch) error
+	ListExpiredArchives(ctx context.Context, now time.Time) ([]types.Batch, error)
+}
+
+// DocumentRepository persists branding, templates, generated documents and batches
+type DocumentRepository struct {
+	db *sql.DB
+}
+
+// Ensure DocumentRepository implements DocumentRepo interface
+var _ DocumentRepo = &DocumentRepository{}
+
+func NewDocumentRepository(db *sql.DB) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const brandingColumns = `organization_id, company_name, logo_url, primary_color, accent_color, address, footer_text, updated_at`
+
+func scanBranding(row rowScanner) (*types.Branding, error) {
+	var b types.Branding
+	err := row.Scan(&b.OrganizationID, &b.CompanyName, &b.LogoURL, &b.PrimaryColor, &b.AccentColor, &b.Address, &b.FooterText, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetBranding returns the organization's branding, or empty branding if none was configured
+func (r *DocumentRepository) GetBranding(ctx context.Context, orgID uuid.UUID) (*types.Branding, error) {
+	query := `SELECT ` + brandingColumns + ` FROM document_branding WHERE organization_id = $1`
+
+	branding, err := scanBranding(r.db.QueryRowContext(ctx, query, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &types.Branding{OrganizationID: orgID}, nil
+		}
+		return nil, fmt.Errorf("failed to get branding: %w", err)
+	}
+	return branding, nil
+}
+
+func (r *DocumentRepository) SaveBranding(ctx context.Context, branding types.Branding) (*types.Branding, error) {
+	query := `
+		INSERT INTO document_branding (organization_id, company_name, logo_url, primary_color, accent_color, address, footer_text, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (organization_id) DO UPDATE SET
+			company_name = EXCLUDED.company_name,
+			logo_url = EXCLUDED.logo_url,
+			primary_color = EXCLUDED.primary_color,
+			accent_color = EXCLUDED.accent_color,
+			address = EXCLUDED.address,
+			footer_text = EXCLUDED.footer_text,
+			updated_at = NOW()
+		RETURNING ` + brandingColumns
+
+	saved, err := scanBranding(r.db.QueryRowContext(ctx, query,
+		branding.OrganizationID, branding.CompanyName, branding.LogoURL, branding.PrimaryColor,
+		branding.AccentColor, branding.Address, branding.FooterText,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save branding: %w", err)
+	}
+	return saved, nil
+}
+
+const templateColumns = `id, organization_id, code, name, kind, active_version, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*types.Template, error) {
+	var t types.Template
+	err := row.Scan(&t.ID, &t.OrganizationID, &t.Code, &t.Name, &t.Kind, &t.ActiveVersion, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *DocumentRepository) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM document_templates WHERE organization_id = $1 ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []types.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (r *DocumentRepository) FindTemplate(ctx context.Context, orgID uuid.UUID, code string) (*types.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM document_templates WHERE organization_id = $1 AND code = $2`
+
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, query, orgID, code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("document template %q %w", code, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find document template: %w", err)
+	}
+	return t, nil
+}
+
+func (r *DocumentRepository) FindTemplateByID(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM document_templates WHERE organization_id = $1 AND id = $2`
+
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("document template %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find document template: %w", err)
+	}
+	return t, nil
+}
+
+const versionColumns = `id, organization_id, template_id, version, body, page_size, orientation, note, created_by, created_at`
+
+func scanVersion(row rowScanner) (*types.TemplateVersion, error) {
+	var v types.TemplateVersion
+	err := row.Scan(&v.ID, &v.OrganizationID, &v.TemplateID, &v.Version, &v.Body, &v.PageSize, &v.Orientation, &v.Note, &v.CreatedBy, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CreateTemplate stores a template and its first version, which is active
+func (r *DocumentRepository) CreateTemplate(ctx context.Context, tmpl types.Template, version types.TemplateVersion) (*types.Template, *types.TemplateVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	savedTemplate, err := scanTemplate(tx.QueryRowContext(ctx, `
+		INSERT INTO document_templates (id, organization_id, code, name, kind, active_version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 1, NOW(), NOW())
+		RETURNING `+templateColumns,
+		tmpl.ID, tmpl.OrganizationID, tmpl.Code, tmpl.Name, tmpl.Kind,
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create document template: %w", err)
+	}
+
+	savedVersion, err := scanVersion(tx.QueryRowContext(ctx, `
+		INSERT INTO document_template_versions (id, organization_id, template_id, version, body, page_size, orientation, note, created_by, created_at)
+		VALUES ($1, $2, $3, 1, $4, $5, $6, $7, $8, NOW())
+		RETURNING `+versionColumns,
+		version.ID, savedTemplate.OrganizationID, savedTemplate.ID, version.Body, version.PageSize,
+		version.Orientation, version.Note, version.CreatedBy,
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create document template version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return savedTemplate, savedVersion, nil
+}
+
+// AddVersion stores the next version of a template. The template row is
+// locked so concurrent saves get distinct version numbers.
+func (r *DocumentRepository) AddVersion(ctx context.Context, version types.TemplateVersion, activate bool) (*types.TemplateVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked uuid.UUID
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM document_templates WHERE organization_id = $1 AND id = $2 FOR UPDATE`,
+		version.OrganizationID, version.TemplateID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("document template %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to lock document template: %w", err)
+	}
+
+	var next int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM document_template_versions WHERE template_id = $1`,
+		version.TemplateID).Scan(&next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to number document template version: %w", err)
+	}
+
+	saved, err := scanVersion(tx.QueryRowContext(ctx, `
+		INSERT INTO document_template_versions (id, organization_id, template_id, version, body, page_size, orientation, note, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING `+versionColumns,
+		version.ID, version.OrganizationID, version.TemplateID, next, version.Body, version.PageSize,
+		version.Orientation, version.Note, version.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document template version: %w", err)
+	}
+
+	if activate {
+		query := `UPDATE document_templates SET active_version = $3, updated_at = NOW() WHERE organization_id = $1 AND id = $2`
+		if _, err := tx.ExecContext(ctx, query, version.OrganizationID, version.TemplateID, next); err != nil {
+			return nil, fmt.Errorf("failed to activate document template version: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *DocumentRepository) ListVersions(ctx context.Context, orgID, templateID uuid.UUID) ([]types.TemplateVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM document_template_versions WHERE organization_id = $1 AND template_id = $2 ORDER BY version DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document template versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []types.TemplateVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document template version: %w", err)
+		}
+		versions = append(versions, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document template versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (r *DocumentRepository) FindVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) (*types.TemplateVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM document_template_versions WHERE organization_id = $1 AND template_id = $2 AND version = $3`
+
+	v, err := scanVersion(r.db.QueryRowContext(ctx, query, orgID, templateID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("document template version %d %w", version, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find document template version: %w", err)
+	}
+	return v, nil
+}
+
+func (r *DocumentRepository) ActivateVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) error {
+	query := `
+		UPDATE document_templates SET active_version = $3, updated_at = NOW()
+		WHERE organization_id = $1 AND id = $2
+			AND EXISTS (SELECT 1 FROM document_template_versions WHERE template_id = $2 AND version = $3)
+	`
+	result, err := r.db.ExecContext(ctx, query, orgID, templateID, version)
+	if err != nil {
+		return fmt.Errorf("failed to activate document template version: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to activate document template version: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("document template version %d %w", version, ErrNotFound)
+	}
+	return nil
+}
+
+const documentColumns = `id, organization_id, template_id, template_version, kind, record_id, batch_id, filename, size, sha256, created_by, created_at`
+
+func scanDocument(row rowScanner, extra ...interface{}) (*types.Document, error) {
+	var d types.Document
+	dest := []interface{}{
+		&d.ID, &d.OrganizationID, &d.TemplateID, &d.TemplateVersion, &d.Kind, &d.RecordID,
+		&d.BatchID, &d.Filename, &d.Size, &d.SHA256, &d.CreatedBy, &d.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *DocumentRepository) SaveDocument(ctx context.Context, doc types.Document) (*types.Document, error) {
+	query := `
+		INSERT INTO generated_documents (id, organization_id, template_id, template_version, kind, record_id, batch_id, filename, size, sha256, content, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		RETURNING ` + documentColumns
+
+	saved, err := scanDocument(r.db.QueryRowContext(ctx, query,
+		doc.ID, doc.OrganizationID, doc.TemplateID, doc.TemplateVersion, doc.Kind, doc.RecordID,
+		doc.BatchID, doc.Filename, doc.Size, doc.SHA256, doc.Content, doc.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+	return saved, nil
+}
+
+// FindDocument returns a generated document including its content
+func (r *DocumentRepository) FindDocument(ctx context.Context, orgID, id uuid.UUID) (*types.Document, error) {
+	query := `SELECT ` + documentColumns + `, content FROM generated_documents WHERE organization_id = $1 AND id = $2`
+
+	var content []byte
+	doc, err := scanDocument(r.db.QueryRowContext(ctx, query, orgID, id), &content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("document %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find document: %w", err)
+	}
+	doc.Content = content
+	return doc, nil
+}
+
+// ListBatchDocuments returns the documents of a batch without their content
+func (r *DocumentRepository) ListBatchDocuments(ctx context.Context, orgID, batchID uuid.UUID) ([]types.Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM generated_documents WHERE organization_id = $1 AND batch_id = $2 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []types.Document
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, *doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list batch documents: %w", err)
+	}
+	return docs, nil
+}
+
+const batchColumns = `id, organization_id, template_id, template_version, status, items, total, succeeded, failed, errors,
+	selection, package, archive_key, archive_size, archive_sha256, archive_expires_at, created_by, created_at, started_at, completed_at`
+
+func scanBatch(row rowScanner) (*types.Batch, error) {
+	var b types.Batch
+	var itemsJSON, errorsJSON, selectionJSON []byte
+	var archiveKey, archiveSHA256 sql.NullString
+	var archiveSize sql.NullInt64
+	var archiveExpiresAt sql.NullTime
+	err := row.Scan(
+		&b.ID, &b.OrganizationID, &b.TemplateID, &b.TemplateVersion, &b.Status, &itemsJSON, &b.Total,
+		&b.Succeeded, &b.Failed, &errorsJSON, &selectionJSON, &b.Package, &archiveKey, &archiveSize,
+		&archiveSHA256, &archiveExpiresAt, &b.CreatedBy, &b.CreatedAt, &b.StartedAt, &b.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(itemsJSON, &b.Items); err != nil {
+		return nil, fmt.Errorf("invalid batch items: %w", err)
+	}
+	if err := json.Unmarshal(errorsJSON, &b.Errors); err != nil {
+		return nil, fmt.Errorf("invalid batch errors: %w", err)
+	}
+	if selectionJSON != nil {
+		if err := json.Unmarshal(selectionJSON, &b.Selection); err != nil {
+			return nil, fmt.Errorf("invalid batch selection: %w", err)
+		}
+	}
+	if archiveKey.Valid {
+		b.Archive = &types.BatchArchive{
+			Key:       archiveKey.String,
+			Size:      archiveSize.Int64,
+			SHA256:    archiveSHA256.String,
+			ExpiresAt: archiveExpiresAt.Time,
+		}
+	}
+	return &b, nil
+}
+
+func (r *DocumentRepository) CreateBatch(ctx context.Context, batch types.Batch) (*types.Batch, error) {
+	itemsJSON, err := json.Marshal(batch.Items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch items: %w", err)
+	}
+
+	var selectionJSON []byte
+	if batch.Selection != nil {
+		if selectionJSON, err = json.Marshal(batch.Selection); err != nil {
+			return nil, fmt.Errorf("failed to marshal batch selection: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO document_batches (id, organization_id, template_id, template_version, status, items, total, selection, package, created_by, created_at)
+		VALUES ($1, $2, $3, $4, 'pending', $5, $6, $7, $8, $9, NOW())
+		RETURNING ` + batchColumns
+
+	saved, err := scanBatch(r.db.QueryRowContext(ctx, query,
+		batch.ID, batch.OrganizationID, batch.TemplateID, batch.TemplateVersion, itemsJSON, batch.Total,
+		selectionJSON, batch.Package, batch.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *DocumentRepository) FindBatch(ctx context.Context, orgID, id uuid.UUID) (*types.Batch, error) {
+	query := `SELECT ` + batchColumns + ` FROM document_batches WHERE organization_id = $1 AND id = $2`
+
+	batch, err := scanBatch(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("batch %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find batch: %w", err)
+	}
+	return batch, nil
+}
+
+// ClaimBatch marks the oldest pending batch as processing and returns it.
+// Batches left processing without progress since staleBefore, e.g. by a
+// crashed instance, are claimed again and resume after their last item.
+// It returns nil when there is nothing to do.
+func (r *DocumentRepository) ClaimBatch(ctx context.Context, staleBefore time.Time) (*types.Batch, error) {
+	query := `
+		UPDATE document_batches
+		SET status = 'processing', started_at = COALESCE(started_at, NOW()), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM document_batches
+			WHERE status = 'pending' OR (status = 'processing' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + batchColumns
+
+	batch, err := scanBatch(r.db.QueryRowContext(ctx, query, staleBefore))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim batch: %w", err)
+	}
+	return batch, nil
+}
+
+// UpdateBatchProgress records the batch's counts, errors, status and archive
+func (r *DocumentRepository) UpdateBatchProgress(ctx context.Context, batch types.Batch) error {
+	errorsJSON, err := json.Marshal(batch.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch errors: %w", err)
+	}
+	if batch.Errors == nil {
+		errorsJSON = []byte("[]")
+	}
+
+	var archiveKey, archiveSHA256 sql.NullString
+	var archiveSize sql.NullInt64
+	var archiveExpiresAt sql.NullTime
+	if batch.Archive != nil {
+		archiveKey = sql.NullString{String: batch.Archive.Key, Valid: true}
+		archiveSize = sql.NullInt64{Int64: batch.Archive.Size, Valid: true}
+		archiveSHA256 = sql.NullString{String: batch.Archive.SHA256, Valid: true}
+		archiveExpiresAt = sql.NullTime{Time: batch.Archive.ExpiresAt, Valid: true}
+	}
+
+	query := `
+		UPDATE document_batches
+		SET status = $3, succeeded = $4, failed = $5, errors = $6, completed_at = $7,
+			archive_key = $8, archive_size = $9, archive_sha256 = $10, archive_expires_at = $11, updated_at = NOW()
+		WHERE organization_id = $1 AND id = $2
+	`
+	if _, err := r.db.ExecContext(ctx, query,
+		batch.OrganizationID, batch.ID, batch.Status, batch.Succeeded, batch.Failed, errorsJSON, batch.CompletedAt,
+		archiveKey, archiveSize, archiveSHA256, archiveExpiresAt,
+	); err != nil {
+		return fmt.Errorf("failed to update batch: %w", err)
+	}
+	return nil
+}
+
+// ListExpiredArchives returns batches whose archive expired before now
+func (r *DocumentRepository) ListExpiredArchives(ctx context.Context, now time.Time) ([]types.Batch, error) {
+	query := `SELECT ` + batchColumns + ` FROM document_batches
+		WHERE archive_key IS NOT NULL AND archive_expires_at < $1
+		ORDER BY archive_expires_at`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired batch archives: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []types.Batch
+	for rows.Next() {
+		batch, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, *batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired batch archives: %w", err)
+	}
+	return batches, nil
+}
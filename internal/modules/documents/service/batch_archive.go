@@ -0,0 +1,218 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// pushTimeout bounds the completion notification, which is sent after the
+// batch is saved
+const pushTimeout = 15 * time.Second
+
+// archiveURL is where the archive of a batch is downloaded
+func archiveURL(batchID uuid.UUID) string {
+	return strings.Replace(BatchArchivePath, ":id", batchID.String(), 1)
+}
+
+// archiveKey is the storage key of a batch's archive
+func archiveKey(batch types.Batch) string {
+	return fmt.Sprintf("document-batches/%s/%s.zip", batch.OrganizationID, batch.ID)
+}
+
+// packageBatch zips the documents generated by a batch and uploads the zip
+// to the file storage
+func (s *DocumentService) packageBatch(ctx context.Context, batch types.Batch) (*types.BatchArchive, error) {
+	if s.files == nil {
+		return nil, ErrStorageUnavailable
+	}
+	documents, err := s.repo.ListBatchDocuments(ctx, batch.OrganizationID, batch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The archive is spooled to disk so its checksum is known before upload
+	tmp, err := os.CreateTemp("", "document-batch-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(tmp, hash)}
+	zw := zip.NewWriter(counter)
+	names := make(map[string]int, len(documents))
+	for _, listed := range documents {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Listed documents carry no content; load each one as it is written
+		document, err := s.repo.FindDocument(ctx, batch.OrganizationID, listed.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load document %s: %w", listed.ID, err)
+		}
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     uniqueEntryName(names, document.Filename),
+			Method:   zip.Store, // PDFs are already compressed
+			Modified: document.CreatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+		if _, err := entry.Write(document.Content); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	archive := &types.BatchArchive{
+		Key:       archiveKey(batch),
+		Size:      counter.n,
+		SHA256:    hex.EncodeToString(hash.Sum(nil)),
+		ExpiresAt: s.now().Add(ArchiveRetention),
+	}
+	if _, err := s.files.Upload(ctx, storage.UploadOptions{
+		Key:         archive.Key,
+		Reader:      tmp,
+		ContentType: "application/zip",
+		Size:        archive.Size,
+		Metadata:    map[string]string{"sha256": archive.SHA256},
+		ACL:         "private",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to upload archive: %w", err)
+	}
+	return archive, nil
+}
+
+// uniqueEntryName numbers repeated file names so no document of a batch
+// overwrites another when the archive is extracted
+func uniqueEntryName(names map[string]int, filename string) string {
+	names[filename]++
+	if n := names[filename]; n > 1 {
+		ext := path.Ext(filename)
+		filename = strings.TrimSuffix(filename, ext) + "-" + strconv.Itoa(n) + ext
+		names[filename]++
+	}
+	return filename
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// notifyRequester tells the user who queued a batch that it finished, with
+// the download link when it was packaged
+func (s *DocumentService) notifyRequester(ctx context.Context, batch types.Batch) {
+	if s.notifier == nil || batch.CreatedBy == nil {
+		return
+	}
+
+	notification := push.Notification{
+		OrganizationID: batch.OrganizationID,
+		UserIDs:        []uuid.UUID{*batch.CreatedBy},
+		Data: map[string]string{
+			"type":     "document_batch_" + string(batch.Status),
+			"batch_id": batch.ID.String(),
+		},
+	}
+	switch {
+	case batch.Status == types.BatchStatusFailed:
+		notification.Title = "Your documents could not be generated"
+		notification.Body = fmt.Sprintf("%d of %d documents failed", batch.Total-batch.Succeeded, batch.Total)
+	case batch.Archive != nil:
+		notification.Title = "Your documents are ready to download"
+		notification.Body = fmt.Sprintf("%d documents were packaged; the download expires %s", batch.Succeeded, batch.Archive.ExpiresAt.Format(time.DateOnly))
+		notification.Data["url"] = archiveURL(batch.ID)
+	default:
+		notification.Title = "Your documents are ready"
+		notification.Body = fmt.Sprintf("%d of %d documents were generated", batch.Succeeded, batch.Total)
+	}
+	if batch.Failed > 0 && batch.Status == types.BatchStatusCompleted {
+		notification.Body += fmt.Sprintf(", %d failed", batch.Failed)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pushTimeout)
+		defer cancel()
+		if err := s.notifier.Send(ctx, notification); err != nil {
+			s.logger.Warn("failed to push document batch completion", "error", err, "batch_id", batch.ID)
+		}
+	}()
+}
+
+// OpenArchive returns a packaged batch with a reader of its archive, which
+// the caller closes
+func (s *DocumentService) OpenArchive(ctx context.Context, orgID, id uuid.UUID) (*types.Batch, io.ReadCloser, error) {
+	batch, err := s.GetBatch(ctx, orgID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if batch.Archive == nil {
+		return nil, nil, ErrArchiveExpired
+	}
+	if s.files == nil {
+		return nil, nil, ErrStorageUnavailable
+	}
+	file, err := s.files.Download(ctx, batch.Archive.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download archive: %w", err)
+	}
+	return batch, file.Reader, nil
+}
+
+// ExpireArchives deletes the archives past their retention and returns how
+// many were deleted. Archives that cannot be deleted are retried next time.
+func (s *DocumentService) ExpireArchives(ctx context.Context) (int, error) {
+	if s.files == nil {
+		return 0, nil
+	}
+	batches, err := s.repo.ListExpiredArchives(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	var errs []error
+	for _, batch := range batches {
+		if err := s.files.Delete(ctx, batch.Archive.Key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete archive of batch %s: %w", batch.ID, err))
+			continue
+		}
+		batch.Archive = nil
+		if err := s.repo.UpdateBatchProgress(ctx, batch); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		expired++
+	}
+	return expired, errors.Join(errs...)
+}
@@ -0,0 +1,778 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxTemplateSize bounds template bodies
+	MaxTemplateSize = 256 * 1024
+	// MaxBatchItems caps the documents of one batch
+	MaxBatchItems = 500
+	// BatchPollInterval is how often queued batches are picked up
+	BatchPollInterval = 10 * time.Second
+	// BatchStaleAfter is how long a processing batch may go without progress
+	// before another instance takes it over
+	BatchStaleAfter = 10 * time.Minute
+	// MaxSelectedItems caps the documents of a batch selecting its records,
+	// e.g. a month of invoices
+	MaxSelectedItems = 5000
+	// ArchiveRetention is how long a packaged batch's archive stays downloadable
+	ArchiveRetention = 7 * 24 * time.Hour
+	// BatchArchivePath is where batch archives are downloaded, with the batch
+	// ID in place of :id
+	BatchArchivePath = "/api/v1/document-batches/:id/archive"
+	// maxParsedTemplates bounds the cache of parsed template versions
+	maxParsedTemplates = 256
+)
+
+var (
+	// ErrInvalid wraps validation failures of templates, branding and generation requests
+	ErrInvalid = errors.New("invalid request")
+	// ErrRendererUnavailable is returned for PDF output when no PDF renderer is configured
+	ErrRendererUnavailable = errors.New("PDF rendering is not available")
+	// ErrNoTemplate is returned when the organization has no template of a kind
+	ErrNoTemplate = errors.New("no document template")
+	// ErrStorageUnavailable is returned for packaged batches when no file storage is configured
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+	// ErrArchiveExpired is returned for the archive of a batch that was not
+	// packaged, is not finished, or whose archive expired
+	ErrArchiveExpired = errors.New("batch archive is not available")
+)
+
+var (
+	codePattern    = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+	colorPattern   = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	filenameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// AuthService defines the permission check used by the document service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// PDFRenderer converts rendered HTML to PDF; *templates.PDFGenerator satisfies it
+type PDFRenderer interface {
+	HTMLToPDF(html string, opts *templates.PDFOptions) ([]byte, error)
+}
+
+// DataSource loads the template data of a record, so callers can generate a
+// document from a record ID instead of supplying the data themselves
+type DataSource interface {
+	Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error)
+}
+
+// RecordSelector is implemented by data sources that can list the records
+// matching a filter, so batches can select their records instead of listing
+// them. Items come in the order they are packaged, with file names.
+type RecordSelector interface {
+	SelectRecords(ctx context.Context, filter types.RecordFilter) ([]types.BatchItem, error)
+}
+
+// FileStore is the file storage batch archives are written to
+type FileStore interface {
+	Upload(ctx context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error)
+	Download(ctx context.Context, key string) (*storage.File, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// DocumentService manages branded, versioned document templates and renders
+// them to PDF, synchronously or in background batches
+type DocumentService struct {
+	repo        repository.DocumentRepo
+	authService AuthService
+	renderer    PDFRenderer
+	files       FileStore
+	notifier    push.Notifier
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu      sync.Mutex
+	sources map[types.DocumentKind]DataSource
+	parsed  map[uuid.UUID]*template.Template
+}
+
+func NewDocumentService(repo repository.DocumentRepo, authService AuthService, logger *slog.Logger) *DocumentService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &DocumentService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+		sources:     make(map[types.DocumentKind]DataSource),
+		parsed:      make(map[uuid.UUID]*template.Template),
+	}
+}
+
+// SetRenderer enables PDF output. Without a renderer, previews are limited to HTML.
+func (s *DocumentService) SetRenderer(renderer PDFRenderer) {
+	s.renderer = renderer
+}
+
+// SetStorage enables packaged batches, whose documents are zipped into an
+// archive in the file storage
+func (s *DocumentService) SetStorage(files FileStore) {
+	s.files = files
+}
+
+// SetPushNotifier sets the notifier that tells requesters their batch is done
+func (s *DocumentService) SetPushNotifier(notifier push.Notifier) {
+	s.notifier = notifier
+}
+
+// RegisterDataSource lets documents of a kind be generated from a record ID,
+// and from a selection of records when the source is a RecordSelector
+func (s *DocumentService) RegisterDataSource(kind types.DocumentKind, source DataSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[kind] = source
+}
+
+func (s *DocumentService) dataSource(kind types.DocumentKind) (DataSource, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, ok := s.sources[kind]
+	return source, ok
+}
+
+// GetBranding returns the organization's document branding
+func (s *DocumentService) GetBranding(ctx context.Context, orgID uuid.UUID) (*types.Branding, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.GetBranding(ctx, orgID)
+}
+
+// UpdateBranding replaces the organization's document branding
+func (s *DocumentService) UpdateBranding(ctx context.Context, orgID uuid.UUID, branding types.Branding) (*types.Branding, error) {
+	if err := s.authService.CheckPermission(ctx, "document_templates:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	branding.OrganizationID = orgID
+	branding.CompanyName = strings.TrimSpace(branding.CompanyName)
+	for name, color := range map[string]string{"primary_color": branding.PrimaryColor, "accent_color": branding.AccentColor} {
+		if color != "" && !colorPattern.MatchString(color) {
+			return nil, fmt.Errorf("%w: %s must be a hex color like #1a2b3c", ErrInvalid, name)
+		}
+	}
+	// The renderer fetches the logo, so only allow public HTTPS locations
+	if branding.LogoURL != "" && !strings.HasPrefix(branding.LogoURL, "https://") {
+		return nil, fmt.Errorf("%w: logo_url must be an https URL", ErrInvalid)
+	}
+
+	return s.repo.SaveBranding(ctx, branding)
+}
+
+// ListTemplates returns the organization's document templates
+func (s *DocumentService) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListTemplates(ctx, orgID)
+}
+
+// GetTemplate returns a template by code
+func (s *DocumentService) GetTemplate(ctx context.Context, orgID uuid.UUID, code string) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindTemplate(ctx, orgID, code)
+}
+
+// CreateTemplate creates a template with its first version
+func (s *DocumentService) CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, req types.TemplateCreateRequest) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "document_templates:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if !codePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code must be 1-50 lowercase letters, digits, dashes or underscores, starting with a letter", ErrInvalid)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if !req.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalid, req.Kind)
+	}
+	if _, err := s.repo.FindTemplate(ctx, orgID, code); err == nil {
+		return nil, fmt.Errorf("%w: template %q already exists", ErrInvalid, code)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	version, err := newVersion(orgID, userID, req.TemplateVersionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, _, err := s.repo.CreateTemplate(ctx, types.Template{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Code:           code,
+		Name:           name,
+		Kind:           req.Kind,
+	}, version)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// SaveTemplateVersion adds a version to a template. Versions are immutable,
+// so documents can always be traced to the exact layout that produced them.
+func (s *DocumentService) SaveTemplateVersion(ctx context.Context, orgID, userID uuid.UUID, code string, req types.TemplateVersionRequest) (*types.TemplateVersion, error) {
+	if err := s.authService.CheckPermission(ctx, "document_templates:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, code)
+	if err != nil {
+		return nil, err
+	}
+	version, err := newVersion(orgID, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	version.TemplateID = tmpl.ID
+
+	return s.repo.AddVersion(ctx, version, !req.Draft)
+}
+
+// ListTemplateVersions returns a template's versions, newest first
+func (s *DocumentService) ListTemplateVersions(ctx context.Context, orgID uuid.UUID, code string) ([]types.TemplateVersion, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(ctx, orgID, tmpl.ID)
+}
+
+// ActivateTemplateVersion makes a version the one used for generation, e.g. to roll back
+func (s *DocumentService) ActivateTemplateVersion(ctx context.Context, orgID uuid.UUID, code string, version int) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "document_templates:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ActivateVersion(ctx, orgID, tmpl.ID, version); err != nil {
+		return nil, err
+	}
+	tmpl.ActiveVersion = version
+	return tmpl, nil
+}
+
+func newVersion(orgID, userID uuid.UUID, req types.TemplateVersionRequest) (types.TemplateVersion, error) {
+	if strings.TrimSpace(req.Body) == "" {
+		return types.TemplateVersion{}, fmt.Errorf("%w: body is required", ErrInvalid)
+	}
+	if len(req.Body) > MaxTemplateSize {
+		return types.TemplateVersion{}, fmt.Errorf("%w: body must be at most %d bytes", ErrInvalid, MaxTemplateSize)
+	}
+	if _, err := parseTemplate(req.Body); err != nil {
+		return types.TemplateVersion{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	switch req.PageSize {
+	case "":
+		req.PageSize = types.PageSizeA4
+	case types.PageSizeA4, types.PageSizeLetter, types.PageSizeLegal:
+	default:
+		return types.TemplateVersion{}, fmt.Errorf("%w: unknown page size %q", ErrInvalid, req.PageSize)
+	}
+	switch req.Orientation {
+	case "":
+		req.Orientation = types.OrientationPortrait
+	case types.OrientationPortrait, types.OrientationLandscape:
+	default:
+		return types.TemplateVersion{}, fmt.Errorf("%w: unknown orientation %q", ErrInvalid, req.Orientation)
+	}
+
+	return types.TemplateVersion{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Body:           req.Body,
+		PageSize:       req.PageSize,
+		Orientation:    req.Orientation,
+		Note:           strings.TrimSpace(req.Note),
+		CreatedBy:      &userID,
+	}, nil
+}
+
+func parseTemplate(body string) (*template.Template, error) {
+	return template.New("document").Funcs(templates.DefaultFuncMap()).Option("missingkey=zero").Parse(body)
+}
+
+// Preview renders a template version without storing the result. It returns
+// the rendered bytes and their content type.
+func (s *DocumentService) Preview(ctx context.Context, orgID uuid.UUID, code string, req types.PreviewRequest) ([]byte, string, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, "", fmt.Errorf("permission denied: %w", err)
+	}
+
+	format := req.Format
+	if format == "" {
+		format = types.PreviewFormatHTML
+	}
+	if format != types.PreviewFormatHTML && format != types.PreviewFormatPDF {
+		return nil, "", fmt.Errorf("%w: unknown format %q", ErrInvalid, format)
+	}
+	if format == types.PreviewFormatPDF && s.renderer == nil {
+		return nil, "", ErrRendererUnavailable
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, code)
+	if err != nil {
+		return nil, "", err
+	}
+	number := tmpl.ActiveVersion
+	if req.Version != nil {
+		number = *req.Version
+	}
+	version, err := s.repo.FindVersion(ctx, orgID, tmpl.ID, number)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data := req.Data
+	if data == nil && req.RecordID != nil {
+		if data, err = s.resolveData(ctx, orgID, tmpl.Kind, req.RecordID, nil); err != nil {
+			return nil, "", err
+		}
+	}
+
+	html, err := s.renderHTML(ctx, orgID, version, data)
+	if err != nil {
+		return nil, "", err
+	}
+	if format == types.PreviewFormatHTML {
+		return []byte(html), "text/html; charset=utf-8", nil
+	}
+
+	pdf, err := s.renderPDF(html, version)
+	if err != nil {
+		return nil, "", err
+	}
+	return pdf, "application/pdf", nil
+}
+
+// Generate renders the template's active version to PDF and stores the document
+func (s *DocumentService) Generate(ctx context.Context, orgID, userID uuid.UUID, req types.GenerateRequest) (*types.Document, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:generate"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.renderer == nil {
+		return nil, ErrRendererUnavailable
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, req.TemplateCode)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.repo.FindVersion(ctx, orgID, tmpl.ID, tmpl.ActiveVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.generate(ctx, tmpl, version, types.BatchItem{RecordID: req.RecordID, Data: req.Data, Filename: req.Filename}, nil, &userID)
+}
+
+// Render renders the organization's template of a kind to PDF without
+// storing the document. It checks no permission: callers authorize the
+// request themselves, as the customer portal does for a customer's own
+// invoices. A template whose code is the kind is preferred over other
+// templates of the kind.
+func (s *DocumentService) Render(ctx context.Context, orgID uuid.UUID, kind types.DocumentKind, data map[string]interface{}) ([]byte, error) {
+	if s.renderer == nil {
+		return nil, ErrRendererUnavailable
+	}
+
+	templates, err := s.repo.ListTemplates(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl *types.Template
+	for i := range templates {
+		if templates[i].Kind == kind && (tmpl == nil || templates[i].Code == string(kind)) {
+			tmpl = &templates[i]
+		}
+	}
+	if tmpl == nil {
+		return nil, fmt.Errorf("%w: the organization has no %s template", ErrNoTemplate, kind)
+	}
+	version, err := s.repo.FindVersion(ctx, orgID, tmpl.ID, tmpl.ActiveVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := s.renderHTML(ctx, orgID, version, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderPDF(html, version)
+}
+
+func (s *DocumentService) generate(ctx context.Context, tmpl *types.Template, version *types.TemplateVersion, item types.BatchItem, batchID, userID *uuid.UUID) (*types.Document, error) {
+	data, err := s.resolveData(ctx, tmpl.OrganizationID, tmpl.Kind, item.RecordID, item.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := s.renderHTML(ctx, tmpl.OrganizationID, version, data)
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := s.renderPDF(html, version)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(pdf)
+	return s.repo.SaveDocument(ctx, types.Document{
+		ID:              uuid.New(),
+		OrganizationID:  tmpl.OrganizationID,
+		TemplateID:      tmpl.ID,
+		TemplateVersion: version.Version,
+		Kind:            tmpl.Kind,
+		RecordID:        item.RecordID,
+		BatchID:         batchID,
+		Filename:        s.filename(tmpl, item),
+		Size:            len(pdf),
+		SHA256:          hex.EncodeToString(sum[:]),
+		Content:         pdf,
+		CreatedBy:       userID,
+	})
+}
+
+func (s *DocumentService) resolveData(ctx context.Context, orgID uuid.UUID, kind types.DocumentKind, recordID *uuid.UUID, data map[string]interface{}) (map[string]interface{}, error) {
+	if data != nil {
+		return data, nil
+	}
+	if recordID == nil {
+		return nil, fmt.Errorf("%w: data or record_id is required", ErrInvalid)
+	}
+	source, ok := s.dataSource(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s documents cannot be generated from a record_id; pass data instead", ErrInvalid, kind)
+	}
+	loaded, err := source.Load(ctx, orgID, *recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s %s: %w", kind, recordID, err)
+	}
+	return loaded, nil
+}
+
+func (s *DocumentService) renderHTML(ctx context.Context, orgID uuid.UUID, version *types.TemplateVersion, data map[string]interface{}) (string, error) {
+	tmpl, err := s.parsedVersion(version)
+	if err != nil {
+		return "", err
+	}
+	branding, err := s.repo.GetBranding(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, types.RenderData{Branding: *branding, Data: data, GeneratedAt: s.now()}); err != nil {
+		return "", fmt.Errorf("%w: failed to render template: %v", ErrInvalid, err)
+	}
+	return buf.String(), nil
+}
+
+// parsedVersion caches parsed templates by version; versions never change
+func (s *DocumentService) parsedVersion(version *types.TemplateVersion) (*template.Template, error) {
+	s.mu.Lock()
+	tmpl, ok := s.parsed[version.ID]
+	s.mu.Unlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := parseTemplate(version.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	s.mu.Lock()
+	if len(s.parsed) >= maxParsedTemplates {
+		s.parsed = make(map[uuid.UUID]*template.Template)
+	}
+	s.parsed[version.ID] = tmpl
+	s.mu.Unlock()
+	return tmpl, nil
+}
+
+func (s *DocumentService) renderPDF(html string, version *types.TemplateVersion) ([]byte, error) {
+	if s.renderer == nil {
+		return nil, ErrRendererUnavailable
+	}
+	opts := templates.DefaultPDFOptions()
+	opts.PageSize = string(version.PageSize)
+	opts.Orientation = string(version.Orientation)
+
+	pdf, err := s.renderer.HTMLToPDF(html, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return pdf, nil
+}
+
+func (s *DocumentService) filename(tmpl *types.Template, item types.BatchItem) string {
+	name := strings.TrimSuffix(strings.TrimSpace(item.Filename), ".pdf")
+	if name == "" {
+		if item.RecordID != nil {
+			name = tmpl.Code + "-" + item.RecordID.String()
+		} else {
+			name = tmpl.Code + "-" + s.now().UTC().Format("20060102-150405")
+		}
+	}
+	name = strings.Trim(filenameUnsafe.ReplaceAllString(name, "_"), "._")
+	if len(name) > 150 {
+		name = name[:150]
+	}
+	if name == "" {
+		name = tmpl.Code
+	}
+	return name + ".pdf"
+}
+
+// GetDocument returns a generated document including its content
+func (s *DocumentService) GetDocument(ctx context.Context, orgID, id uuid.UUID) (*types.Document, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDocument(ctx, orgID, id)
+}
+
+// CreateBatch queues documents for background generation. The batch is
+// pinned to the template's active version so later edits do not change it,
+// and a selection is resolved to the records it matches now.
+func (s *DocumentService) CreateBatch(ctx context.Context, orgID, userID uuid.UUID, req types.BatchRequest) (*types.Batch, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:generate"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.renderer == nil {
+		return nil, ErrRendererUnavailable
+	}
+	if req.Package && s.files == nil {
+		return nil, ErrStorageUnavailable
+	}
+	if req.Selection != nil && len(req.Items) > 0 {
+		return nil, fmt.Errorf("%w: give either items or a selection", ErrInvalid)
+	}
+	if req.Selection == nil && len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrInvalid)
+	}
+	if len(req.Items) > MaxBatchItems {
+		return nil, fmt.Errorf("%w: a batch may have at most %d items", ErrInvalid, MaxBatchItems)
+	}
+
+	tmpl, err := s.repo.FindTemplate(ctx, orgID, req.TemplateCode)
+	if err != nil {
+		return nil, err
+	}
+	source, hasSource := s.dataSource(tmpl.Kind)
+
+	items := req.Items
+	if req.Selection != nil {
+		if items, err = s.selectRecords(ctx, orgID, tmpl.Kind, source, *req.Selection); err != nil {
+			return nil, err
+		}
+	}
+	for i, item := range items {
+		if item.Data == nil && (item.RecordID == nil || !hasSource) {
+			return nil, fmt.Errorf("%w: item %d needs data or a record_id of a kind with a data source", ErrInvalid, i)
+		}
+	}
+
+	batch, err := s.repo.CreateBatch(ctx, types.Batch{
+		ID:              uuid.New(),
+		OrganizationID:  orgID,
+		TemplateID:      tmpl.ID,
+		TemplateVersion: tmpl.ActiveVersion,
+		Items:           items,
+		Total:           len(items),
+		Selection:       req.Selection,
+		Package:         req.Package,
+		CreatedBy:       &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("document batch queued", "batch_id", batch.ID, "template", tmpl.Code, "items", batch.Total, "package", batch.Package)
+	return batch, nil
+}
+
+// selectRecords resolves a selection with the record selector of the kind
+func (s *DocumentService) selectRecords(ctx context.Context, orgID uuid.UUID, kind types.DocumentKind, source DataSource, selection types.RecordSelection) ([]types.BatchItem, error) {
+	selector, ok := source.(RecordSelector)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s documents cannot be selected; list the items instead", ErrInvalid, kind)
+	}
+
+	filter := types.RecordFilter{OrganizationID: orgID, RouteID: selection.RouteID, Limit: MaxSelectedItems + 1}
+	for _, bound := range []struct {
+		name  string
+		value string
+		dest  **time.Time
+	}{{"from", selection.From, &filter.From}, {"to", selection.To, &filter.To}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date like 2025-03-31", ErrInvalid, bound.name)
+		}
+		*bound.dest = &date
+	}
+	if filter.From == nil && filter.To == nil && filter.RouteID == nil {
+		return nil, fmt.Errorf("%w: a selection needs from, to or route_id", ErrInvalid)
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalid)
+	}
+
+	items, err := selector.SelectRecords(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select %s records: %w", kind, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no %s records match the selection", ErrInvalid, kind)
+	}
+	if len(items) > MaxSelectedItems {
+		return nil, fmt.Errorf("%w: the selection matches more than %d records; narrow it", ErrInvalid, MaxSelectedItems)
+	}
+	return items, nil
+}
+
+// GetBatch returns a batch with its progress and, once packaged, its archive
+func (s *DocumentService) GetBatch(ctx context.Context, orgID, id uuid.UUID) (*types.Batch, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	batch, err := s.repo.FindBatch(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Archive != nil {
+		batch.Archive.URL = archiveURL(batch.ID)
+	}
+	return batch, nil
+}
+
+// ListBatchDocuments returns the documents a batch has generated so far
+func (s *DocumentService) ListBatchDocuments(ctx context.Context, orgID, id uuid.UUID) ([]types.Document, error) {
+	if err := s.authService.CheckPermission(ctx, "documents:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindBatch(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListBatchDocuments(ctx, orgID, id)
+}
+
+// ProcessNextBatch claims one queued batch and generates its remaining
+// documents. Progress is saved after every item, so a batch taken over from
+// a crashed instance resumes where it stopped. It reports whether a batch
+// was processed.
+func (s *DocumentService) ProcessNextBatch(ctx context.Context) (bool, error) {
+	batch, err := s.repo.ClaimBatch(ctx, s.now().Add(-BatchStaleAfter))
+	if err != nil || batch == nil {
+		return false, err
+	}
+	logger := s.logger.With("batch_id", batch.ID, "organization_id", batch.OrganizationID)
+
+	fail := func(reason error) (bool, error) {
+		logger.Error("document batch failed", "error", reason)
+		now := s.now()
+		batch.Status = types.BatchStatusFailed
+		batch.CompletedAt = &now
+		batch.Errors = append(batch.Errors, types.BatchItemError{Index: -1, Error: reason.Error()})
+		if err := s.repo.UpdateBatchProgress(ctx, *batch); err != nil {
+			return true, err
+		}
+		s.notifyRequester(ctx, *batch)
+		return true, nil
+	}
+
+	tmpl, err := s.repo.FindTemplateByID(ctx, batch.OrganizationID, batch.TemplateID)
+	if err != nil {
+		return fail(err)
+	}
+	version, err := s.repo.FindVersion(ctx, batch.OrganizationID, tmpl.ID, batch.TemplateVersion)
+	if err != nil {
+		return fail(err)
+	}
+
+	for i := batch.Succeeded + batch.Failed; i < len(batch.Items); i++ {
+		if ctx.Err() != nil {
+			// Leave the batch processing; it is resumed once it goes stale
+			return true, ctx.Err()
+		}
+
+		if _, err := s.generate(ctx, tmpl, version, batch.Items[i], &batch.ID, batch.CreatedBy); err != nil {
+			batch.Failed++
+			batch.Errors = append(batch.Errors, types.BatchItemError{Index: i, Error: err.Error()})
+		} else {
+			batch.Succeeded++
+		}
+		if err := s.repo.UpdateBatchProgress(ctx, *batch); err != nil {
+			return true, err
+		}
+	}
+
+	if batch.Succeeded == 0 {
+		return fail(errors.New("no document was generated"))
+	}
+	if batch.Package {
+		archive, err := s.packageBatch(ctx, *batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Leave the batch processing; it is packaged once it goes stale
+				return true, ctx.Err()
+			}
+			return fail(err)
+		}
+		batch.Archive = archive
+	}
+
+	now := s.now()
+	batch.Status = types.BatchStatusCompleted
+	batch.CompletedAt = &now
+	logger.Info("document batch finished", "succeeded", batch.Succeeded, "failed", batch.Failed, "packaged", batch.Archive != nil)
+	if err := s.repo.UpdateBatchProgress(ctx, *batch); err != nil {
+		return true, err
+	}
+	s.notifyRequester(ctx, *batch)
+	return true, nil
+}
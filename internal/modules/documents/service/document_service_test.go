@@ -0,0 +1,557 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+	"github.com/KevTiv/alieze-erp/pkg/templates"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDocumentRepo struct {
+	branding  types.Branding
+	templates map[uuid.UUID]types.Template
+	versions  []types.TemplateVersion
+	documents map[uuid.UUID]types.Document
+	batches   map[uuid.UUID]types.Batch
+}
+
+func newFakeDocumentRepo() *fakeDocumentRepo {
+	return &fakeDocumentRepo{
+		templates: make(map[uuid.UUID]types.Template),
+		documents: make(map[uuid.UUID]types.Document),
+		batches:   make(map[uuid.UUID]types.Batch),
+	}
+}
+
+func (f *fakeDocumentRepo) GetBranding(ctx context.Context, orgID uuid.UUID) (*types.Branding, error) {
+	b := f.branding
+	b.OrganizationID = orgID
+	return &b, nil
+}
+
+func (f *fakeDocumentRepo) SaveBranding(ctx context.Context, branding types.Branding) (*types.Branding, error) {
+	f.branding = branding
+	return &branding, nil
+}
+
+func (f *fakeDocumentRepo) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.Template, error) {
+	var list []types.Template
+	for _, t := range f.templates {
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+func (f *fakeDocumentRepo) FindTemplate(ctx context.Context, orgID uuid.UUID, code string) (*types.Template, error) {
+	for _, t := range f.templates {
+		if t.OrganizationID == orgID && t.Code == code {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("template %w", repository.ErrNotFound)
+}
+
+func (f *fakeDocumentRepo) FindTemplateByID(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error) {
+	t, ok := f.templates[id]
+	if !ok || t.OrganizationID != orgID {
+		return nil, fmt.Errorf("template %w", repository.ErrNotFound)
+	}
+	return &t, nil
+}
+
+func (f *fakeDocumentRepo) CreateTemplate(ctx context.Context, tmpl types.Template, version types.TemplateVersion) (*types.Template, *types.TemplateVersion, error) {
+	tmpl.ActiveVersion = 1
+	version.TemplateID = tmpl.ID
+	version.Version = 1
+	f.templates[tmpl.ID] = tmpl
+	f.versions = append(f.versions, version)
+	return &tmpl, &version, nil
+}
+
+func (f *fakeDocumentRepo) AddVersion(ctx context.Context, version types.TemplateVersion, activate bool) (*types.TemplateVersion, error) {
+	next := 1
+	for _, v := range f.versions {
+		if v.TemplateID == version.TemplateID && v.Version >= next {
+			next = v.Version + 1
+		}
+	}
+	version.Version = next
+	f.versions = append(f.versions, version)
+	if activate {
+		t := f.templates[version.TemplateID]
+		t.ActiveVersion = next
+		f.templates[t.ID] = t
+	}
+	return &version, nil
+}
+
+func (f *fakeDocumentRepo) ListVersions(ctx context.Context, orgID, templateID uuid.UUID) ([]types.TemplateVersion, error) {
+	var list []types.TemplateVersion
+	for _, v := range f.versions {
+		if v.TemplateID == templateID {
+			list = append(list, v)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeDocumentRepo) FindVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) (*types.TemplateVersion, error) {
+	for _, v := range f.versions {
+		if v.TemplateID == templateID && v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, fmt.Errorf("template version %w", repository.ErrNotFound)
+}
+
+func (f *fakeDocumentRepo) ActivateVersion(ctx context.Context, orgID, templateID uuid.UUID, version int) error {
+	if _, err := f.FindVersion(ctx, orgID, templateID, version); err != nil {
+		return err
+	}
+	t := f.templates[templateID]
+	t.ActiveVersion = version
+	f.templates[templateID] = t
+	return nil
+}
+
+func (f *fakeDocumentRepo) SaveDocument(ctx context.Context, doc types.Document) (*types.Document, error) {
+	f.documents[doc.ID] = doc
+	return &doc, nil
+}
+
+func (f *fakeDocumentRepo) FindDocument(ctx context.Context, orgID, id uuid.UUID) (*types.Document, error) {
+	d, ok := f.documents[id]
+	if !ok || d.OrganizationID != orgID {
+		return nil, fmt.Errorf("document %w", repository.ErrNotFound)
+	}
+	return &d, nil
+}
+
+func (f *fakeDocumentRepo) ListBatchDocuments(ctx context.Context, orgID, batchID uuid.UUID) ([]types.Document, error) {
+	var list []types.Document
+	for _, d := range f.documents {
+		if d.BatchID != nil && *d.BatchID == batchID {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeDocumentRepo) CreateBatch(ctx context.Context, batch types.Batch) (*types.Batch, error) {
+	batch.Status = types.BatchStatusPending
+	f.batches[batch.ID] = batch
+	return &batch, nil
+}
+
+func (f *fakeDocumentRepo) FindBatch(ctx context.Context, orgID, id uuid.UUID) (*types.Batch, error) {
+	b, ok := f.batches[id]
+	if !ok || b.OrganizationID != orgID {
+		return nil, fmt.Errorf("batch %w", repository.ErrNotFound)
+	}
+	return &b, nil
+}
+
+func (f *fakeDocumentRepo) ClaimBatch(ctx context.Context, staleBefore time.Time) (*types.Batch, error) {
+	for id, b := range f.batches {
+		if b.Status == types.BatchStatusPending {
+			b.Status = types.BatchStatusProcessing
+			f.batches[id] = b
+			return &b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDocumentRepo) UpdateBatchProgress(ctx context.Context, batch types.Batch) error {
+	f.batches[batch.ID] = batch
+	return nil
+}
+
+func (f *fakeDocumentRepo) ListExpiredArchives(ctx context.Context, now time.Time) ([]types.Batch, error) {
+	var list []types.Batch
+	for _, b := range f.batches {
+		if b.Archive != nil && b.Archive.ExpiresAt.Before(now) {
+			list = append(list, b)
+		}
+	}
+	return list, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+// fakeRenderer returns the HTML as the "PDF" so tests can inspect it
+type fakeRenderer struct {
+	opts []*templates.PDFOptions
+}
+
+func (r *fakeRenderer) HTMLToPDF(html string, opts *templates.PDFOptions) ([]byte, error) {
+	r.opts = append(r.opts, opts)
+	if strings.Contains(html, "explode") {
+		return nil, errors.New("renderer crashed")
+	}
+	return []byte(html), nil
+}
+
+type fakeDataSource map[uuid.UUID]map[string]interface{}
+
+func (f fakeDataSource) Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error) {
+	data, ok := f[recordID]
+	if !ok {
+		return nil, fmt.Errorf("record %w", repository.ErrNotFound)
+	}
+	return data, nil
+}
+
+// fakeSelectorSource selects every record it holds in the filter's date range
+type fakeSelectorSource struct {
+	fakeDataSource
+	filters []types.RecordFilter
+}
+
+func (f *fakeSelectorSource) SelectRecords(ctx context.Context, filter types.RecordFilter) ([]types.BatchItem, error) {
+	f.filters = append(f.filters, filter)
+	var items []types.BatchItem
+	for id, data := range f.fakeDataSource {
+		items = append(items, types.BatchItem{RecordID: &id, Filename: data["number"].(string)})
+	}
+	return items, nil
+}
+
+type fakeFileStore struct {
+	files map[string][]byte
+}
+
+func (f *fakeFileStore) Upload(ctx context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error) {
+	content, err := io.ReadAll(opts.Reader)
+	if err != nil {
+		return nil, err
+	}
+	f.files[opts.Key] = content
+	return &storage.FileMetadata{Key: opts.Key, Size: int64(len(content))}, nil
+}
+
+func (f *fakeFileStore) Download(ctx context.Context, key string) (*storage.File, error) {
+	content, ok := f.files[key]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return &storage.File{Reader: io.NopCloser(bytes.NewReader(content))}, nil
+}
+
+func (f *fakeFileStore) Delete(ctx context.Context, key string) error {
+	delete(f.files, key)
+	return nil
+}
+
+type fakeNotifier chan push.Notification
+
+func (f fakeNotifier) Send(ctx context.Context, notification push.Notification) error {
+	f <- notification
+	return nil
+}
+
+const invoiceBody = `<h1 style="color: {{.Branding.PrimaryColor}}">{{.Branding.CompanyName}}</h1><p>Invoice {{.Data.number}}</p>`
+
+func newTestService(t *testing.T) (*DocumentService, *fakeDocumentRepo, *fakeRenderer, uuid.UUID) {
+	t.Helper()
+	repo := newFakeDocumentRepo()
+	renderer := &fakeRenderer{}
+	svc := NewDocumentService(repo, allowAll{}, nil)
+	svc.SetRenderer(renderer)
+	svc.now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC) }
+
+	orgID := uuid.New()
+	_, err := svc.UpdateBranding(context.Background(), orgID, types.Branding{CompanyName: "Acme", PrimaryColor: "#112233"})
+	require.NoError(t, err)
+	_, err = svc.CreateTemplate(context.Background(), orgID, uuid.New(), types.TemplateCreateRequest{
+		Code:                   "invoice",
+		Name:                   "Invoice",
+		Kind:                   types.DocumentKindInvoice,
+		TemplateVersionRequest: types.TemplateVersionRequest{Body: invoiceBody},
+	})
+	require.NoError(t, err)
+	return svc, repo, renderer, orgID
+}
+
+func TestCreateTemplateValidates(t *testing.T) {
+	svc, _, _, orgID := newTestService(t)
+	ctx := context.Background()
+
+	cases := []types.TemplateCreateRequest{
+		{Code: "Bad Code", Name: "x", Kind: types.DocumentKindQuote, TemplateVersionRequest: types.TemplateVersionRequest{Body: "x"}},
+		{Code: "quote", Name: "x", Kind: "letter", TemplateVersionRequest: types.TemplateVersionRequest{Body: "x"}},
+		{Code: "quote", Name: "x", Kind: types.DocumentKindQuote, TemplateVersionRequest: types.TemplateVersionRequest{Body: "{{.Data"}},
+		{Code: "quote", Name: "x", Kind: types.DocumentKindQuote, TemplateVersionRequest: types.TemplateVersionRequest{Body: "x", PageSize: "A3"}},
+		{Code: "invoice", Name: "x", Kind: types.DocumentKindInvoice, TemplateVersionRequest: types.TemplateVersionRequest{Body: "x"}},
+	}
+	for _, req := range cases {
+		_, err := svc.CreateTemplate(ctx, orgID, uuid.New(), req)
+		assert.ErrorIs(t, err, ErrInvalid, "request %+v", req)
+	}
+}
+
+func TestUpdateBrandingValidates(t *testing.T) {
+	svc, _, _, orgID := newTestService(t)
+
+	_, err := svc.UpdateBranding(context.Background(), orgID, types.Branding{PrimaryColor: "red"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = svc.UpdateBranding(context.Background(), orgID, types.Branding{LogoURL: "file:///etc/passwd"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestPreviewAppliesBrandingAndEscapes(t *testing.T) {
+	svc, _, renderer, orgID := newTestService(t)
+
+	body, contentType, err := svc.Preview(context.Background(), orgID, "invoice", types.PreviewRequest{
+		Data: map[string]interface{}{"number": "<script>INV-1</script>"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+	assert.Contains(t, string(body), "Acme")
+	assert.Contains(t, string(body), "#112233")
+	assert.NotContains(t, string(body), "<script>")
+	assert.Empty(t, renderer.opts, "HTML previews do not render a PDF")
+
+	_, contentType, err = svc.Preview(context.Background(), orgID, "invoice", types.PreviewRequest{
+		Data:   map[string]interface{}{"number": "INV-1"},
+		Format: types.PreviewFormatPDF,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", contentType)
+}
+
+func TestTemplateVersioning(t *testing.T) {
+	svc, _, _, orgID := newTestService(t)
+	ctx := context.Background()
+	data := map[string]interface{}{"number": "INV-1"}
+
+	draft, err := svc.SaveTemplateVersion(ctx, orgID, uuid.New(), "invoice", types.TemplateVersionRequest{Body: "<p>v2 {{.Data.number}}</p>", Draft: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, draft.Version)
+
+	// Drafts can be previewed but are not used for generation until activated
+	body, _, err := svc.Preview(ctx, orgID, "invoice", types.PreviewRequest{Version: &draft.Version, Data: data})
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "v2 INV-1")
+
+	doc, err := svc.Generate(ctx, orgID, uuid.New(), types.GenerateRequest{TemplateCode: "invoice", Data: data})
+	require.NoError(t, err)
+	assert.Equal(t, 1, doc.TemplateVersion)
+
+	_, err = svc.ActivateTemplateVersion(ctx, orgID, "invoice", 2)
+	require.NoError(t, err)
+	doc, err = svc.Generate(ctx, orgID, uuid.New(), types.GenerateRequest{TemplateCode: "invoice", Data: data})
+	require.NoError(t, err)
+	assert.Equal(t, 2, doc.TemplateVersion)
+
+	_, err = svc.ActivateTemplateVersion(ctx, orgID, "invoice", 9)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestGenerateStoresDocument(t *testing.T) {
+	svc, repo, renderer, orgID := newTestService(t)
+	ctx := context.Background()
+	recordID := uuid.New()
+	svc.RegisterDataSource(types.DocumentKindInvoice, fakeDataSource{recordID: {"number": "INV-7"}})
+
+	doc, err := svc.Generate(ctx, orgID, uuid.New(), types.GenerateRequest{TemplateCode: "invoice", RecordID: &recordID})
+	require.NoError(t, err)
+	assert.Equal(t, "invoice-"+recordID.String()+".pdf", doc.Filename)
+	assert.Len(t, doc.SHA256, 64)
+	assert.Contains(t, string(repo.documents[doc.ID].Content), "INV-7")
+	require.Len(t, renderer.opts, 1)
+	assert.Equal(t, "A4", renderer.opts[0].PageSize)
+
+	doc, err = svc.Generate(ctx, orgID, uuid.New(), types.GenerateRequest{
+		TemplateCode: "invoice",
+		Data:         map[string]interface{}{"number": "INV-8"},
+		Filename:     "../../etc/INV 8.pdf",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "etc_INV_8.pdf", doc.Filename)
+
+	_, err = svc.Generate(ctx, orgID, uuid.New(), types.GenerateRequest{TemplateCode: "invoice"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestRenderUsesTemplateOfKind(t *testing.T) {
+	svc, repo, _, orgID := newTestService(t)
+	ctx := context.Background()
+	_, err := svc.CreateTemplate(ctx, orgID, uuid.New(), types.TemplateCreateRequest{
+		Code:                   "a-invoice-compact",
+		Name:                   "Compact invoice",
+		Kind:                   types.DocumentKindInvoice,
+		TemplateVersionRequest: types.TemplateVersionRequest{Body: "<p>compact {{.Data.number}}</p>"},
+	})
+	require.NoError(t, err)
+
+	pdf, err := svc.Render(ctx, orgID, types.DocumentKindInvoice, map[string]interface{}{"number": "INV-9"})
+	require.NoError(t, err)
+	assert.Contains(t, string(pdf), "Invoice INV-9", "the template coded after the kind is preferred")
+	assert.Empty(t, repo.documents, "rendered documents are not stored")
+
+	_, err = svc.Render(ctx, orgID, types.DocumentKindQuote, nil)
+	assert.ErrorIs(t, err, ErrNoTemplate)
+
+	svc.SetRenderer(nil)
+	_, err = svc.Render(ctx, orgID, types.DocumentKindInvoice, nil)
+	assert.ErrorIs(t, err, ErrRendererUnavailable)
+}
+
+func TestGenerateWithoutRenderer(t *testing.T) {
+	svc, _, _, orgID := newTestService(t)
+	svc.SetRenderer(nil)
+
+	_, err := svc.Generate(context.Background(), orgID, uuid.New(), types.GenerateRequest{TemplateCode: "invoice", Data: map[string]interface{}{}})
+	assert.ErrorIs(t, err, ErrRendererUnavailable)
+
+	_, _, err = svc.Preview(context.Background(), orgID, "invoice", types.PreviewRequest{Data: map[string]interface{}{}})
+	assert.NoError(t, err, "HTML previews work without a renderer")
+}
+
+func TestProcessNextBatch(t *testing.T) {
+	svc, repo, _, orgID := newTestService(t)
+	ctx := context.Background()
+
+	batch, err := svc.CreateBatch(ctx, orgID, uuid.New(), types.BatchRequest{
+		TemplateCode: "invoice",
+		Items: []types.BatchItem{
+			{Data: map[string]interface{}{"number": "INV-1"}},
+			{Data: map[string]interface{}{"number": "explode"}},
+			{Data: map[string]interface{}{"number": "INV-3"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.BatchStatusPending, batch.Status)
+
+	// Editing the template after queueing does not change the batch
+	_, err = svc.SaveTemplateVersion(ctx, orgID, uuid.New(), "invoice", types.TemplateVersionRequest{Body: "<p>v2</p>"})
+	require.NoError(t, err)
+
+	processed, err := svc.ProcessNextBatch(ctx)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	done := repo.batches[batch.ID]
+	assert.Equal(t, types.BatchStatusCompleted, done.Status)
+	assert.Equal(t, 2, done.Succeeded)
+	assert.Equal(t, 1, done.Failed)
+	require.Len(t, done.Errors, 1)
+	assert.Equal(t, 1, done.Errors[0].Index)
+
+	docs, err := svc.ListBatchDocuments(ctx, orgID, batch.ID)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	for _, doc := range docs {
+		assert.Equal(t, 1, doc.TemplateVersion)
+	}
+
+	processed, err = svc.ProcessNextBatch(ctx)
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestCreateBatchValidatesItems(t *testing.T) {
+	svc, _, _, orgID := newTestService(t)
+	recordID := uuid.New()
+
+	// Record IDs need a data source for the template's kind
+	_, err := svc.CreateBatch(context.Background(), orgID, uuid.New(), types.BatchRequest{
+		TemplateCode: "invoice",
+		Items:        []types.BatchItem{{RecordID: &recordID}},
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.CreateBatch(context.Background(), orgID, uuid.New(), types.BatchRequest{
+		TemplateCode: "invoice",
+		Items:        make([]types.BatchItem, MaxBatchItems+1),
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestPackagedBatchFromSelection(t *testing.T) {
+	svc, repo, _, orgID := newTestService(t)
+	ctx := context.Background()
+	files := &fakeFileStore{files: make(map[string][]byte)}
+	notifier := make(fakeNotifier, 1)
+	source := &fakeSelectorSource{fakeDataSource: fakeDataSource{
+		uuid.New(): {"number": "INV-1"},
+		uuid.New(): {"number": "INV-2"},
+		uuid.New(): {"number": "INV-2"},
+	}}
+	svc.RegisterDataSource(types.DocumentKindInvoice, source)
+
+	march := types.RecordSelection{From: "2025-03-01", To: "2025-03-31"}
+	_, err := svc.CreateBatch(ctx, orgID, uuid.New(), types.BatchRequest{TemplateCode: "invoice", Selection: &march, Package: true})
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+
+	svc.SetStorage(files)
+	svc.SetPushNotifier(notifier)
+	backwards := types.RecordSelection{From: "2025-03-31", To: "2025-03-01"}
+	_, err = svc.CreateBatch(ctx, orgID, uuid.New(), types.BatchRequest{TemplateCode: "invoice", Selection: &backwards, Package: true})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	userID := uuid.New()
+	batch, err := svc.CreateBatch(ctx, orgID, userID, types.BatchRequest{TemplateCode: "invoice", Selection: &march, Package: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, batch.Total)
+	require.Len(t, source.filters, 1)
+	assert.Equal(t, "2025-03-31", source.filters[0].To.Format(time.DateOnly))
+	assert.Equal(t, MaxSelectedItems+1, source.filters[0].Limit)
+
+	processed, err := svc.ProcessNextBatch(ctx)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	done, err := svc.GetBatch(ctx, orgID, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.BatchStatusCompleted, done.Status)
+	require.NotNil(t, done.Archive)
+	assert.Equal(t, "/api/v1/document-batches/"+batch.ID.String()+"/archive", done.Archive.URL)
+
+	notification := <-notifier
+	assert.Equal(t, []uuid.UUID{userID}, notification.UserIDs)
+	assert.Equal(t, done.Archive.URL, notification.Data["url"])
+
+	_, archive, err := svc.OpenArchive(ctx, orgID, batch.ID)
+	require.NoError(t, err)
+	content, err := io.ReadAll(archive)
+	require.NoError(t, err)
+	assert.Equal(t, done.Archive.Size, int64(len(content)))
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"INV-1.pdf", "INV-2-2.pdf", "INV-2.pdf"}, names, "repeated file names are numbered")
+
+	// The archive is deleted once its retention passes
+	svc.now = func() time.Time { return done.Archive.ExpiresAt.Add(time.Minute) }
+	expired, err := svc.ExpireArchives(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Empty(t, files.files)
+	assert.Nil(t, repo.batches[batch.ID].Archive)
+	_, _, err = svc.OpenArchive(ctx, orgID, batch.ID)
+	assert.ErrorIs(t, err, ErrArchiveExpired)
+}
@@ -0,0 +1,241 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentKind is the business document a template prints
+type DocumentKind string
+
+const (
+	DocumentKindQuote           DocumentKind = "quote"
+	DocumentKindInvoice         DocumentKind = "invoice"
+	DocumentKindProofOfDelivery DocumentKind = "proof_of_delivery"
+	DocumentKindQCCertificate   DocumentKind = "qc_certificate"
+	DocumentKindRouteManifest   DocumentKind = "route_manifest"
+	DocumentKindVisitorBadge    DocumentKind = "visitor_badge"
+	DocumentKindOther           DocumentKind = "other"
+)
+
+// IsValid reports whether the kind is supported
+func (k DocumentKind) IsValid() bool {
+	switch k {
+	case DocumentKindQuote, DocumentKindInvoice, DocumentKindProofOfDelivery,
+		DocumentKindQCCertificate, DocumentKindRouteManifest, DocumentKindVisitorBadge, DocumentKindOther:
+		return true
+	}
+	return false
+}
+
+// PageSize is the paper size of a rendered document
+type PageSize string
+
+const (
+	PageSizeA4     PageSize = "A4"
+	PageSizeLetter PageSize = "Letter"
+	PageSizeLegal  PageSize = "Legal"
+)
+
+// Orientation is the page orientation of a rendered document
+type Orientation string
+
+const (
+	OrientationPortrait  Orientation = "Portrait"
+	OrientationLandscape Orientation = "Landscape"
+)
+
+// Branding is an organization's look applied to every document. Templates
+// reach it as .Branding.
+type Branding struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	CompanyName    string    `json:"company_name"`
+	LogoURL        string    `json:"logo_url,omitempty"`
+	PrimaryColor   string    `json:"primary_color,omitempty"`
+	AccentColor    string    `json:"accent_color,omitempty"`
+	Address        string    `json:"address,omitempty"`
+	FooterText     string    `json:"footer_text,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Template is a named, versioned document layout. Generation uses the active
+// version unless a specific one is requested.
+type Template struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	Code           string       `json:"code"`
+	Name           string       `json:"name"`
+	Kind           DocumentKind `json:"kind"`
+	ActiveVersion  int          `json:"active_version"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// TemplateVersion is an immutable revision of a template's layout
+type TemplateVersion struct {
+	ID             uuid.UUID   `json:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	TemplateID     uuid.UUID   `json:"template_id"`
+	Version        int         `json:"version"`
+	Body           string      `json:"body"`
+	PageSize       PageSize    `json:"page_size"`
+	Orientation    Orientation `json:"orientation"`
+	Note           string      `json:"note,omitempty"`
+	CreatedBy      *uuid.UUID  `json:"created_by,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// TemplateCreateRequest creates a template with its first version
+type TemplateCreateRequest struct {
+	Code string       `json:"code"`
+	Name string       `json:"name"`
+	Kind DocumentKind `json:"kind"`
+	TemplateVersionRequest
+}
+
+// TemplateVersionRequest adds a version to a template. New versions become
+// active unless Draft is set.
+type TemplateVersionRequest struct {
+	Body        string      `json:"body"`
+	PageSize    PageSize    `json:"page_size,omitempty"`
+	Orientation Orientation `json:"orientation,omitempty"`
+	Note        string      `json:"note,omitempty"`
+	Draft       bool        `json:"draft,omitempty"`
+}
+
+// PreviewFormat selects what a preview returns
+type PreviewFormat string
+
+const (
+	PreviewFormatHTML PreviewFormat = "html"
+	PreviewFormatPDF  PreviewFormat = "pdf"
+)
+
+// PreviewRequest renders a template without storing the result
+type PreviewRequest struct {
+	// Version defaults to the active version
+	Version  *int                   `json:"version,omitempty"`
+	RecordID *uuid.UUID             `json:"record_id,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Format   PreviewFormat          `json:"format,omitempty"`
+}
+
+// GenerateRequest renders and stores one document. The template's data comes
+// from Data or, when only RecordID is given, from the data source registered
+// for the template's kind.
+type GenerateRequest struct {
+	TemplateCode string                 `json:"template_code"`
+	RecordID     *uuid.UUID             `json:"record_id,omitempty"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+	Filename     string                 `json:"filename,omitempty"`
+}
+
+// Document is a generated PDF
+type Document struct {
+	ID              uuid.UUID    `json:"id"`
+	OrganizationID  uuid.UUID    `json:"organization_id"`
+	TemplateID      uuid.UUID    `json:"template_id"`
+	TemplateVersion int          `json:"template_version"`
+	Kind            DocumentKind `json:"kind"`
+	RecordID        *uuid.UUID   `json:"record_id,omitempty"`
+	BatchID         *uuid.UUID   `json:"batch_id,omitempty"`
+	Filename        string       `json:"filename"`
+	Size            int          `json:"size"`
+	SHA256          string       `json:"sha256"`
+	Content         []byte       `json:"-"`
+	CreatedBy       *uuid.UUID   `json:"created_by,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// BatchStatus is the lifecycle state of a batch
+type BatchStatus string
+
+const (
+	BatchStatusPending    BatchStatus = "pending"
+	BatchStatusProcessing BatchStatus = "processing"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusFailed     BatchStatus = "failed"
+)
+
+// BatchItem is one document of a batch
+type BatchItem struct {
+	RecordID *uuid.UUID             `json:"record_id,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Filename string                 `json:"filename,omitempty"`
+}
+
+// BatchItemError records why an item of a batch was not generated
+type BatchItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// RecordSelection picks the records of a batch by criteria instead of
+// listing them, e.g. all invoices of March or all deliveries of a route
+type RecordSelection struct {
+	// From and To bound the records' date, inclusive, as YYYY-MM-DD
+	From    string     `json:"from,omitempty"`
+	To      string     `json:"to,omitempty"`
+	RouteID *uuid.UUID `json:"route_id,omitempty"`
+}
+
+// RecordFilter is a parsed RecordSelection, passed to the record selector of
+// the template's kind. From and To are midnight UTC of their dates and both
+// dates are included. Limit caps the records returned.
+type RecordFilter struct {
+	OrganizationID uuid.UUID
+	From           *time.Time
+	To             *time.Time
+	RouteID        *uuid.UUID
+	Limit          int
+}
+
+// BatchRequest queues many documents from one template for background
+// generation, from either listed items or a selection of records. Packaged
+// batches are also zipped into one archive to download.
+type BatchRequest struct {
+	TemplateCode string           `json:"template_code"`
+	Items        []BatchItem      `json:"items,omitempty"`
+	Selection    *RecordSelection `json:"selection,omitempty"`
+	Package      bool             `json:"package,omitempty"`
+}
+
+// BatchArchive is the zip of a packaged batch's documents, kept in file
+// storage until it expires
+type BatchArchive struct {
+	Key       string    `json:"-"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// URL is where the archive is downloaded
+	URL string `json:"url"`
+}
+
+// Batch is a background generation of many documents
+type Batch struct {
+	ID              uuid.UUID        `json:"id"`
+	OrganizationID  uuid.UUID        `json:"organization_id"`
+	TemplateID      uuid.UUID        `json:"template_id"`
+	TemplateVersion int              `json:"template_version"`
+	Status          BatchStatus      `json:"status"`
+	Items           []BatchItem      `json:"-"`
+	Total           int              `json:"total"`
+	Succeeded       int              `json:"succeeded"`
+	Failed          int              `json:"failed"`
+	Errors          []BatchItemError `json:"errors,omitempty"`
+	Selection       *RecordSelection `json:"selection,omitempty"`
+	Package         bool             `json:"package"`
+	Archive         *BatchArchive    `json:"archive,omitempty"`
+	CreatedBy       *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+}
+
+// RenderData is what templates execute against
+type RenderData struct {
+	Branding    Branding
+	Data        map[string]interface{}
+	GeneratedAt time.Time
+}
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/edi"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxInterchangeBytes bounds the size of an inbound interchange
+const maxInterchangeBytes = 10 << 20
+
+// EDIHandler handles trading partners, inbound interchanges, outbound
+// documents and the message log
+type EDIHandler struct {
+	service *service.EDIService
+}
+
+func NewEDIHandler(service *service.EDIService) *EDIHandler {
+	return &EDIHandler{service: service}
+}
+
+func (h *EDIHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/edi/partners", h.ListPartners)
+	router.PUT("/api/edi/partners", h.SavePartner)
+	router.GET("/api/edi/partners/:id", h.GetPartner)
+
+	router.POST("/api/edi/inbound", h.ReceiveInterchange)
+	router.POST("/api/edi/shipments/:id/asn", h.SendShipNotice)
+	router.POST("/api/edi/invoices/:id/outbound", h.SendInvoice)
+
+	router.GET("/api/edi/messages", h.ListMessages)
+	router.GET("/api/edi/messages/:id", h.GetMessage)
+	router.GET("/api/edi/messages/:id/payload", h.GetPayload)
+	router.POST("/api/edi/messages/:id/sent", h.MarkSent)
+}
+
+// ListPartners handles GET /api/edi/partners
+func (h *EDIHandler) ListPartners(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	partners, err := h.service.ListPartners(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partners)
+}
+
+// SavePartner handles PUT /api/edi/partners, creating or updating the
+// partner of the request's contact
+func (h *EDIHandler) SavePartner(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.SavePartner(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// GetPartner handles GET /api/edi/partners/:id
+func (h *EDIHandler) GetPartner(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.GetPartner(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// ReceiveInterchange handles POST /api/edi/inbound. The body is the raw X12
+// or EDIFACT interchange, as delivered by the VAN or AS2 gateway.
+func (h *EDIHandler) ReceiveInterchange(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInterchangeBytes))
+	if err != nil {
+		http.Error(w, "Interchange too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := h.service.ReceiveInterchange(r.Context(), authCtx.OrganizationID, authCtx.UserID, payload)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// SendShipNotice handles POST /api/edi/shipments/:id/asn
+func (h *EDIHandler) SendShipNotice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipment ID", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.service.SendShipNotice(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, message)
+}
+
+// SendInvoice handles POST /api/edi/invoices/:id/outbound
+func (h *EDIHandler) SendInvoice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.service.SendInvoice(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, message)
+}
+
+// ListMessages handles GET /api/edi/messages with optional partner_id,
+// direction, status, document_type, entity_id, limit and offset filters
+func (h *EDIHandler) ListMessages(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.MessageFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Direction:      types.Direction(q.Get("direction")),
+		Status:         types.MessageStatus(q.Get("status")),
+		DocumentType:   edi.DocumentType(q.Get("document_type")),
+	}
+	for name, dest := range map[string]**uuid.UUID{"partner_id": &filter.PartnerID, "entity_id": &filter.EntityID} {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = &id
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, _ = strconv.Atoi(v)
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, _ = strconv.Atoi(v)
+	}
+
+	messages, err := h.service.ListMessages(r.Context(), filter)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// GetMessage handles GET /api/edi/messages/:id
+func (h *EDIHandler) GetMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	message, ok := h.findMessage(w, r, ps)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, message)
+}
+
+// GetPayload handles GET /api/edi/messages/:id/payload, returning the raw
+// interchange
+func (h *EDIHandler) GetPayload(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	message, ok := h.findMessage(w, r, ps)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, message.Payload)
+}
+
+func (h *EDIHandler) findMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (*types.Message, bool) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	message, err := h.service.GetMessage(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeEDIError(w, err)
+		return nil, false
+	}
+	return message, true
+}
+
+// MarkSent handles POST /api/edi/messages/:id/sent
+func (h *EDIHandler) MarkSent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.service.MarkSent(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeEDIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, message)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEDIError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrUnknownPartner):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
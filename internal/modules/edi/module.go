@@ -0,0 +1,68 @@
+package edi
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EDIModule represents the EDI integration module
+type EDIModule struct {
+	ediHandler *handler.EDIHandler
+	logger     *slog.Logger
+}
+
+// NewEDIModule creates a new EDI module
+func NewEDIModule() *EDIModule {
+	return &EDIModule{}
+}
+
+// Name returns the module name
+func (m *EDIModule) Name() string {
+	return "edi"
+}
+
+// Init initializes the EDI module
+func (m *EDIModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "edi")
+	m.logger.Info("Initializing EDI module")
+
+	// Create repositories
+	ediRepo := repository.NewEDIRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	ediService := service.NewEDIService(ediRepo, authAdapter, m.logger)
+
+	// Create handlers
+	m.ediHandler = handler.NewEDIHandler(ediService)
+
+	m.logger.Info("EDI module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers EDI module routes
+func (m *EDIModule) RegisterRoutes(router interface{}) {
+	if m.ediHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.ediHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the EDI module
+func (m *EDIModule) RegisterEventHandlers(bus interface{}) {
+	// ASNs and invoices are sent on request; nothing to consume
+}
+
+// Health checks the health of the EDI module
+func (m *EDIModule) Health() error {
+	return nil
+}
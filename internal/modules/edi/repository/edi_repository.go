@@ -0,0 +1,650 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/types"
+	"github.com/KevTiv/alieze-erp/pkg/edi"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a partner, message or source document does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when another partner already uses an interchange ID
+	ErrConflict = errors.New("interchange ID already used by another partner")
+	// ErrDuplicateOrder is returned when the partner's PO number already has a sales order
+	ErrDuplicateOrder = errors.New("purchase order already received")
+	// ErrUnmapped is returned when a document references products or
+	// currencies that do not exist
+	ErrUnmapped = errors.New("unknown reference in document")
+)
+
+// EDIRepo defines the interface for EDI repository operations
+type EDIRepo interface {
+	ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error)
+	FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error)
+	FindPartnerByInterchange(ctx context.Context, orgID uuid.UUID, qualifier, id string) (*types.Partner, error)
+	FindPartnerByContact(ctx context.Context, orgID, contactID uuid.UUID) (*types.Partner, error)
+	SavePartner(ctx context.Context, orgID uuid.UUID, request types.PartnerRequest) (*types.Partner, error)
+	NextControlNumber(ctx context.Context, orgID, partnerID uuid.UUID) (int64, error)
+	CreateSalesOrder(ctx context.Context, orgID, userID uuid.UUID, partner types.Partner, po edi.PurchaseOrder) (uuid.UUID, error)
+	ShipNoticeSource(ctx context.Context, orgID, shipmentID uuid.UUID) (*types.ShipNoticeSource, error)
+	InvoiceSource(ctx context.Context, orgID, invoiceID uuid.UUID) (*types.InvoiceSource, error)
+	SaveMessage(ctx context.Context, message types.Message) (*types.Message, error)
+	ListMessages(ctx context.Context, filter types.MessageFilter) ([]types.Message, error)
+	FindMessage(ctx context.Context, orgID, id uuid.UUID) (*types.Message, error)
+	MarkSent(ctx context.Context, orgID, id uuid.UUID, sentAt time.Time) error
+}
+
+// EDIRepository stores trading partners and the message log, and reads and
+// writes the sales documents EDI messages map to
+type EDIRepository struct {
+	db *sql.DB
+}
+
+// Ensure EDIRepository implements EDIRepo interface
+var _ EDIRepo = &EDIRepository{}
+
+func NewEDIRepository(db *sql.DB) *EDIRepository {
+	return &EDIRepository{db: db}
+}
+
+const partnerColumns = `
+	id, organization_id, contact_id, name, standard, interchange_id, interchange_qualifier,
+	our_interchange_id, our_interchange_qualifier, test, active, last_control_number, created_at, updated_at
+`
+
+func scanPartner(row interface{ Scan(...interface{}) error }) (*types.Partner, error) {
+	var p types.Partner
+	err := row.Scan(&p.ID, &p.OrganizationID, &p.ContactID, &p.Name, &p.Standard, &p.InterchangeID,
+		&p.InterchangeQualifier, &p.OurInterchangeID, &p.OurInterchangeQualifier, &p.Test, &p.Active,
+		&p.LastControlNumber, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *EDIRepository) findPartner(ctx context.Context, where string, args ...interface{}) (*types.Partner, error) {
+	p, err := scanPartner(r.db.QueryRowContext(ctx, `SELECT `+partnerColumns+` FROM edi_partners WHERE `+where, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("EDI partner %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find EDI partner: %w", err)
+	}
+	return p, nil
+}
+
+func (r *EDIRepository) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+partnerColumns+` FROM edi_partners WHERE organization_id = $1 ORDER BY name`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EDI partners: %w", err)
+	}
+	defer rows.Close()
+
+	partners := []types.Partner{}
+	for rows.Next() {
+		p, err := scanPartner(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan EDI partner: %w", err)
+		}
+		partners = append(partners, *p)
+	}
+	return partners, rows.Err()
+}
+
+func (r *EDIRepository) FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	return r.findPartner(ctx, `organization_id = $1 AND id = $2`, orgID, id)
+}
+
+// FindPartnerByInterchange finds the partner sending with an interchange ID.
+// Partners configured without a qualifier match any qualifier.
+func (r *EDIRepository) FindPartnerByInterchange(ctx context.Context, orgID uuid.UUID, qualifier, id string) (*types.Partner, error) {
+	return r.findPartner(ctx, `
+		organization_id = $1 AND interchange_id = $2 AND interchange_qualifier IN ('', $3)
+		ORDER BY interchange_qualifier DESC
+		LIMIT 1
+	`, orgID, id, qualifier)
+}
+
+// FindPartnerByContact finds the partner of a contact or of its parent, so
+// documents for a customer's stores reach the customer's partner
+func (r *EDIRepository) FindPartnerByContact(ctx context.Context, orgID, contactID uuid.UUID) (*types.Partner, error) {
+	return r.findPartner(ctx, `
+		organization_id = $1
+		AND contact_id IN ($2, (SELECT parent_id FROM contacts WHERE id = $2 AND organization_id = $1))
+		ORDER BY contact_id = $2 DESC
+		LIMIT 1
+	`, orgID, contactID)
+}
+
+// SavePartner creates or updates the partner of a contact
+func (r *EDIRepository) SavePartner(ctx context.Context, orgID uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL)
+	`, request.ContactID, orgID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contact: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("contact %w", ErrNotFound)
+	}
+
+	active := request.Active == nil || *request.Active
+	p, err := scanPartner(r.db.QueryRowContext(ctx, `
+		INSERT INTO edi_partners (
+			organization_id, contact_id, name, standard, interchange_id, interchange_qualifier,
+			our_interchange_id, our_interchange_qualifier, test, active
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (organization_id, contact_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			standard = EXCLUDED.standard,
+			interchange_id = EXCLUDED.interchange_id,
+			interchange_qualifier = EXCLUDED.interchange_qualifier,
+			our_interchange_id = EXCLUDED.our_interchange_id,
+			our_interchange_qualifier = EXCLUDED.our_interchange_qualifier,
+			test = EXCLUDED.test,
+			active = EXCLUDED.active,
+			updated_at = now()
+		RETURNING `+partnerColumns,
+		orgID, request.ContactID, request.Name, request.Standard, request.InterchangeID, request.InterchangeQualifier,
+		request.OurInterchangeID, request.OurInterchangeQualifier, request.Test, active))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", ErrConflict, request.InterchangeID)
+		}
+		return nil, fmt.Errorf("failed to save EDI partner: %w", err)
+	}
+	return p, nil
+}
+
+// NextControlNumber reserves the partner's next interchange control number
+func (r *EDIRepository) NextControlNumber(ctx context.Context, orgID, partnerID uuid.UUID) (int64, error) {
+	var next int64
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE edi_partners
+		SET last_control_number = CASE WHEN last_control_number >= 999999999 THEN 1 ELSE last_control_number + 1 END,
+			updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+		RETURNING last_control_number
+	`, partnerID, orgID).Scan(&next)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("EDI partner %w", ErrNotFound)
+		}
+		return 0, fmt.Errorf("failed to reserve control number: %w", err)
+	}
+	return next, nil
+}
+
+// CreateSalesOrder creates a draft sales order for the partner's contact from
+// an inbound purchase order. Lines are matched to products by internal
+// reference or barcode, and the ship-to party to a child contact of the
+// customer with the party ID as its reference, created when missing.
+func (r *EDIRepository) CreateSalesOrder(ctx context.Context, orgID, userID uuid.UUID, partner types.Partner, po edi.PurchaseOrder) (uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var duplicate bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM sales_orders
+			WHERE organization_id = $1 AND partner_id = $2 AND client_order_ref = $3
+				AND state <> 'cancel' AND deleted_at IS NULL
+		)
+	`, orgID, partner.ContactID, po.Number).Scan(&duplicate)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to check for duplicate orders: %w", err)
+	}
+	if duplicate {
+		return uuid.Nil, fmt.Errorf("%w: %s", ErrDuplicateOrder, po.Number)
+	}
+
+	type orderLine struct {
+		item      edi.LineItem
+		productID uuid.UUID
+		name      string
+		price     float64
+	}
+	lines := make([]orderLine, 0, len(po.Lines))
+	var missing []string
+	for _, item := range po.Lines {
+		codes := []string{}
+		for _, c := range []string{item.ProductCode, item.VendorCode} {
+			if c != "" {
+				codes = append(codes, c)
+			}
+		}
+		line := orderLine{item: item}
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, name, COALESCE(list_price, 0)
+			FROM products
+			WHERE organization_id = $1 AND deleted_at IS NULL AND COALESCE(active, true)
+				AND (default_code = ANY($2) OR barcode = ANY($2))
+			ORDER BY default_code = ANY($2) DESC
+			LIMIT 1
+		`, orgID, pq.Array(codes)).Scan(&line.productID, &line.name, &line.price)
+		if errors.Is(err, sql.ErrNoRows) {
+			missing = append(missing, item.ProductCode)
+			continue
+		}
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to match product: %w", err)
+		}
+		if item.Description != "" {
+			line.name = item.Description
+		}
+		if item.UnitPrice > 0 {
+			line.price = item.UnitPrice
+		}
+		lines = append(lines, line)
+	}
+	if len(missing) > 0 {
+		return uuid.Nil, fmt.Errorf("%w: products %s", ErrUnmapped, strings.Join(missing, ", "))
+	}
+
+	var currencyID *uuid.UUID
+	if po.Currency != "" {
+		var id uuid.UUID
+		err := tx.QueryRowContext(ctx, `SELECT id FROM currencies WHERE code = $1`, po.Currency).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("%w: currency %s", ErrUnmapped, po.Currency)
+		}
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to find currency: %w", err)
+		}
+		currencyID = &id
+	}
+
+	shippingID := partner.ContactID
+	if shipTo := po.ShipTo(); shipTo != nil {
+		if shippingID, err = shipToContact(ctx, tx, orgID, userID, partner.ContactID, *shipTo); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	var total float64
+	for _, l := range lines {
+		total += l.item.Quantity * l.price
+	}
+	orderDate := po.Date
+	if orderDate.IsZero() {
+		orderDate = time.Now()
+	}
+	metadata := map[string]interface{}{
+		"edi_partner_id":     partner.ID,
+		"edi_control_number": po.ControlNumber,
+	}
+	if po.DeliveryDate != nil {
+		metadata["requested_delivery_date"] = po.DeliveryDate.Format("2006-01-02")
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	var orderID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO sales_orders (
+			organization_id, company_id, name, date_order, partner_id, partner_invoice_id, partner_shipping_id,
+			amount_untaxed, amount_total, state, currency_id, client_order_ref, origin, metadata, created_by, updated_by
+		)
+		SELECT $1, c.company_id, $3, $4, c.id, c.id, $5, $6, $6, 'draft', $7, $8, $9, $10, $11, $11
+		FROM contacts c
+		WHERE c.id = $2
+		RETURNING id
+	`, orgID, partner.ContactID, "EDI/"+po.Number, orderDate, shippingID, total, currencyID, po.Number,
+		"EDI "+partner.Name, metadataJSON, userID).Scan(&orderID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create sales order: %w", err)
+	}
+
+	for i, l := range lines {
+		// The buyer's line number and part number are echoed in ASNs and invoices
+		customFields, err := json.Marshal(map[string]string{
+			"edi_line_number":  l.item.LineNumber,
+			"edi_product_code": l.item.ProductCode,
+		})
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to marshal line fields: %w", err)
+		}
+		subtotal := l.item.Quantity * l.price
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO sales_order_lines (
+				organization_id, order_id, sequence, name, product_id, product_uom_qty, price_unit,
+				price_subtotal, price_total, custom_fields, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $10, $10)
+		`, orgID, orderID, (i+1)*10, l.name, l.productID, l.item.Quantity, l.price, subtotal, customFields, userID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create sales order line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit sales order: %w", err)
+	}
+	return orderID, nil
+}
+
+// shipToContact finds or creates the delivery contact of a ship-to party
+// under the customer
+func shipToContact(ctx context.Context, tx *sql.Tx, orgID, userID, customerID uuid.UUID, party edi.Party) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM contacts
+		WHERE organization_id = $1 AND parent_id = $2 AND deleted_at IS NULL
+			AND (($3 <> '' AND reference = $3) OR ($3 = '' AND name = $4))
+		ORDER BY created_at
+		LIMIT 1
+	`, orgID, customerID, party.ID, party.Name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to find ship-to contact: %w", err)
+	}
+
+	name := party.Name
+	if name == "" {
+		name = "Ship-to " + party.ID
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO contacts (
+			organization_id, company_id, contact_type, name, parent_id, is_customer, street, city, zip,
+			country_id, reference, created_by, updated_by
+		)
+		SELECT $1, p.company_id, 'company', $3, p.id, true, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''),
+			(SELECT id FROM countries WHERE code = NULLIF($7, '')), NULLIF($8, ''), $9, $9
+		FROM contacts p
+		WHERE p.id = $2
+		RETURNING id
+	`, orgID, customerID, name, party.Street, party.City, party.PostalCode, party.Country, party.ID, userID).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create ship-to contact: %w", err)
+	}
+	return id, nil
+}
+
+// ShipNoticeSource reads a shipment, its picking's moves and the customer PO
+// number of the sales order the picking was created from
+func (r *EDIRepository) ShipNoticeSource(ctx context.Context, orgID, shipmentID uuid.UUID) (*types.ShipNoticeSource, error) {
+	src := types.ShipNoticeSource{ShipmentID: shipmentID}
+	var customerID uuid.NullUUID
+	var pickingID uuid.UUID
+	var orderID uuid.NullUUID
+	var shipTo edi.Party
+	err := r.db.QueryRowContext(ctx, `
+		SELECT s.status, s.picking_id, p.name, COALESCE(s.departed_at, p.date_done, s.updated_at),
+			COALESCE(s.carrier_code, ''), COALESCE(s.carrier_name, ''), COALESCE(s.tracking_number, ''),
+			p.partner_id, COALESCE(c.reference, ''), COALESCE(c.name, ''), COALESCE(c.street, ''),
+			COALESCE(c.city, ''), COALESCE(c.zip, ''), COALESCE(co.code, ''),
+			so.id, COALESCE(so.client_order_ref, '')
+		FROM delivery_shipments s
+		JOIN stock_pickings p ON p.id = s.picking_id
+		LEFT JOIN contacts c ON c.id = p.partner_id
+		LEFT JOIN countries co ON co.id = c.country_id
+		LEFT JOIN sales_orders so ON so.organization_id = p.organization_id AND so.name = p.origin AND so.deleted_at IS NULL
+		WHERE s.id = $1 AND s.organization_id = $2 AND s.deleted_at IS NULL
+	`, shipmentID, orgID).Scan(&src.Status, &pickingID, &src.Notice.ShipmentID, &src.Notice.ShipDate,
+		&src.Notice.CarrierCode, &src.Notice.CarrierName, &src.Notice.TrackingNumber,
+		&customerID, &shipTo.ID, &shipTo.Name, &shipTo.Street, &shipTo.City, &shipTo.PostalCode, &shipTo.Country,
+		&orderID, &src.Notice.PurchaseOrder)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("shipment %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find shipment: %w", err)
+	}
+	if customerID.Valid {
+		src.CustomerID = customerID.UUID
+	}
+	shipTo.Qualifier = "ST"
+	src.Notice.ShipTo = shipTo
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(sol.custom_fields->>'edi_line_number', ''), COALESCE(sol.custom_fields->>'edi_product_code', ''),
+			COALESCE(pr.default_code, ''), COALESCE(NULLIF(m.quantity, 0), m.product_uom_qty)
+		FROM stock_moves m
+		JOIN products pr ON pr.id = m.product_id
+		LEFT JOIN LATERAL (
+			SELECT custom_fields FROM sales_order_lines
+			WHERE order_id = $3 AND product_id = m.product_id AND deleted_at IS NULL
+			ORDER BY sequence
+			LIMIT 1
+		) sol ON true
+		WHERE m.picking_id = $1 AND m.organization_id = $2 AND m.state <> 'cancel' AND m.deleted_at IS NULL
+		ORDER BY m.sequence, m.created_at
+	`, pickingID, orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipment moves: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line edi.LineItem
+		if err := rows.Scan(&line.LineNumber, &line.ProductCode, &line.VendorCode, &line.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment move: %w", err)
+		}
+		if line.LineNumber == "" {
+			line.LineNumber = strconv.Itoa(len(src.Notice.Lines) + 1)
+		}
+		if line.ProductCode == "" {
+			line.ProductCode = line.VendorCode
+		}
+		src.Notice.Lines = append(src.Notice.Lines, line)
+	}
+	return &src, rows.Err()
+}
+
+// InvoiceSource reads an invoice, its product lines and the customer PO
+// number of the sales order it originates from
+func (r *EDIRepository) InvoiceSource(ctx context.Context, orgID, invoiceID uuid.UUID) (*types.InvoiceSource, error) {
+	src := types.InvoiceSource{InvoiceID: invoiceID}
+	var orderID uuid.NullUUID
+	billTo := edi.Party{Qualifier: "BT"}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT i.move_type, COALESCE(i.state, 'draft'), COALESCE(i.name, ''), COALESCE(i.invoice_date, i.date),
+			COALESCE(cur.code, ''), COALESCE(i.amount_total, 0), i.partner_id,
+			COALESCE(c.reference, ''), c.name, COALESCE(c.street, ''), COALESCE(c.city, ''), COALESCE(c.zip, ''),
+			COALESCE(co.code, ''), so.id, COALESCE(so.client_order_ref, '')
+		FROM invoices i
+		JOIN contacts c ON c.id = i.partner_id
+		LEFT JOIN currencies cur ON cur.id = i.currency_id
+		LEFT JOIN countries co ON co.id = c.country_id
+		LEFT JOIN sales_orders so ON so.organization_id = i.organization_id AND so.name = i.invoice_origin AND so.deleted_at IS NULL
+		WHERE i.id = $1 AND i.organization_id = $2 AND i.deleted_at IS NULL
+	`, invoiceID, orgID).Scan(&src.MoveType, &src.State, &src.Invoice.Number, &src.Invoice.Date,
+		&src.Invoice.Currency, &src.Invoice.TotalAmount, &src.CustomerID,
+		&billTo.ID, &billTo.Name, &billTo.Street, &billTo.City, &billTo.PostalCode, &billTo.Country,
+		&orderID, &src.Invoice.PurchaseOrder)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("invoice %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find invoice: %w", err)
+	}
+	src.Invoice.BillTo = billTo
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(sol.custom_fields->>'edi_line_number', ''), COALESCE(sol.custom_fields->>'edi_product_code', ''),
+			COALESCE(pr.default_code, ''), COALESCE(l.name, pr.name, ''), COALESCE(l.quantity, 0), COALESCE(l.price_unit, 0)
+		FROM invoice_lines l
+		LEFT JOIN products pr ON pr.id = l.product_id
+		LEFT JOIN LATERAL (
+			SELECT custom_fields FROM sales_order_lines
+			WHERE order_id = $3 AND product_id = l.product_id AND deleted_at IS NULL
+			ORDER BY sequence
+			LIMIT 1
+		) sol ON true
+		WHERE l.move_id = $1 AND l.organization_id = $2 AND l.deleted_at IS NULL
+			AND (l.display_type = 'product' OR (l.display_type IS NULL AND l.product_id IS NOT NULL))
+			AND NOT COALESCE(l.exclude_from_invoice_tab, false)
+		ORDER BY l.sequence, l.created_at
+	`, invoiceID, orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoice lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line edi.LineItem
+		if err := rows.Scan(&line.LineNumber, &line.ProductCode, &line.VendorCode, &line.Description,
+			&line.Quantity, &line.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		if line.LineNumber == "" {
+			line.LineNumber = strconv.Itoa(len(src.Invoice.Lines) + 1)
+		}
+		if line.ProductCode == "" {
+			line.ProductCode = line.VendorCode
+		}
+		src.Invoice.Lines = append(src.Invoice.Lines, line)
+	}
+	return &src, rows.Err()
+}
+
+const messageColumns = `
+	id, organization_id, partner_id, direction, COALESCE(standard, ''), COALESCE(document_type, ''),
+	COALESCE(interchange_control_number, ''), COALESCE(control_number, ''), COALESCE(reference, ''), status,
+	COALESCE(error, ''), COALESCE(entity_type, ''), entity_id, created_by, created_at, sent_at
+`
+
+func scanMessage(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*types.Message, error) {
+	var m types.Message
+	var partnerID, entityID, createdBy uuid.NullUUID
+	var sentAt sql.NullTime
+	dest := []interface{}{&m.ID, &m.OrganizationID, &partnerID, &m.Direction, &m.Standard, &m.DocumentType,
+		&m.InterchangeControlNumber, &m.ControlNumber, &m.Reference, &m.Status, &m.Error, &m.EntityType,
+		&entityID, &createdBy, &m.CreatedAt, &sentAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if partnerID.Valid {
+		m.PartnerID = &partnerID.UUID
+	}
+	if entityID.Valid {
+		m.EntityID = &entityID.UUID
+	}
+	if createdBy.Valid {
+		m.CreatedBy = &createdBy.UUID
+	}
+	if sentAt.Valid {
+		m.SentAt = &sentAt.Time
+	}
+	return &m, nil
+}
+
+func (r *EDIRepository) SaveMessage(ctx context.Context, message types.Message) (*types.Message, error) {
+	m, err := scanMessage(r.db.QueryRowContext(ctx, `
+		INSERT INTO edi_messages (
+			organization_id, partner_id, direction, standard, document_type, interchange_control_number,
+			control_number, reference, status, error, entity_type, entity_id, payload, created_by
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9,
+			NULLIF($10, ''), NULLIF($11, ''), $12, $13, $14)
+		RETURNING `+messageColumns,
+		message.OrganizationID, message.PartnerID, message.Direction, message.Standard, message.DocumentType,
+		message.InterchangeControlNumber, message.ControlNumber, message.Reference, message.Status, message.Error,
+		message.EntityType, message.EntityID, message.Payload, message.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to log EDI message: %w", err)
+	}
+	m.Payload = message.Payload
+	return m, nil
+}
+
+func (r *EDIRepository) ListMessages(ctx context.Context, filter types.MessageFilter) ([]types.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM edi_messages WHERE organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+
+	if filter.PartnerID != nil {
+		args = append(args, *filter.PartnerID)
+		query += fmt.Sprintf(" AND partner_id = $%d", len(args))
+	}
+	if filter.Direction != "" {
+		args = append(args, filter.Direction)
+		query += fmt.Sprintf(" AND direction = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.DocumentType != "" {
+		args = append(args, filter.DocumentType)
+		query += fmt.Sprintf(" AND document_type = $%d", len(args))
+	}
+	if filter.EntityID != nil {
+		args = append(args, *filter.EntityID)
+		query += fmt.Sprintf(" AND entity_id = $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EDI messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []types.Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan EDI message: %w", err)
+		}
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+// FindMessage returns a logged message with its payload
+func (r *EDIRepository) FindMessage(ctx context.Context, orgID, id uuid.UUID) (*types.Message, error) {
+	var payload string
+	m, err := scanMessage(r.db.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`, payload FROM edi_messages WHERE id = $1 AND organization_id = $2
+	`, id, orgID), &payload)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("EDI message %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find EDI message: %w", err)
+	}
+	m.Payload = payload
+	return m, nil
+}
+
+func (r *EDIRepository) MarkSent(ctx context.Context, orgID, id uuid.UUID, sentAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE edi_messages SET status = 'sent', sent_at = $3
+		WHERE id = $1 AND organization_id = $2 AND direction = 'outbound'
+	`, id, orgID, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark EDI message sent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("EDI message %w", ErrNotFound)
+	}
+	return nil
+}
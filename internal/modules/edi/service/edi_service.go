@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/types"
+	"github.com/KevTiv/alieze-erp/pkg/edi"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMessageLimit is the page size of the message log
+	DefaultMessageLimit = 50
+	// MaxMessageLimit bounds the page size of the message log
+	MaxMessageLimit = 200
+)
+
+var (
+	// ErrInvalid wraps validation failures of partners and documents
+	ErrInvalid = errors.New("invalid request")
+	// ErrUnknownPartner is returned for interchanges from senders without a
+	// partner and for documents to customers without one
+	ErrUnknownPartner = errors.New("no EDI partner")
+)
+
+// AuthService defines the permission check used by the EDI service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// EDIService exchanges X12 and EDIFACT documents with trading partners:
+// inbound purchase orders become draft sales orders, and delivery shipments
+// and invoices are sent as ASNs and invoices. Every document is logged.
+type EDIService struct {
+	repo        repository.EDIRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewEDIService(repo repository.EDIRepo, authService AuthService, logger *slog.Logger) *EDIService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EDIService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ListPartners returns the organization's trading partners
+func (s *EDIService) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListPartners(ctx, orgID)
+}
+
+// GetPartner returns a trading partner
+func (s *EDIService) GetPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindPartner(ctx, orgID, id)
+}
+
+// SavePartner creates or updates the trading partner of a contact
+func (s *EDIService) SavePartner(ctx context.Context, orgID uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	request.InterchangeID = strings.TrimSpace(request.InterchangeID)
+	request.InterchangeQualifier = strings.TrimSpace(request.InterchangeQualifier)
+	request.OurInterchangeID = strings.TrimSpace(request.OurInterchangeID)
+	request.OurInterchangeQualifier = strings.TrimSpace(request.OurInterchangeQualifier)
+
+	if request.ContactID == uuid.Nil {
+		return nil, fmt.Errorf("%w: contact_id is required", ErrInvalid)
+	}
+	if request.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if !request.Standard.IsValid() {
+		return nil, fmt.Errorf("%w: standard must be x12 or edifact", ErrInvalid)
+	}
+	if request.InterchangeID == "" || request.OurInterchangeID == "" {
+		return nil, fmt.Errorf("%w: interchange_id and our_interchange_id are required", ErrInvalid)
+	}
+
+	// ISA identifiers are fixed width; UNB allows longer ones
+	maxID, maxQualifier := 35, 4
+	if request.Standard == edi.StandardX12 {
+		maxID, maxQualifier = 15, 2
+	}
+	for _, id := range []string{request.InterchangeID, request.OurInterchangeID} {
+		if len(id) > maxID {
+			return nil, fmt.Errorf("%w: %s interchange IDs are at most %d characters", ErrInvalid, request.Standard, maxID)
+		}
+	}
+	for _, q := range []string{request.InterchangeQualifier, request.OurInterchangeQualifier} {
+		if len(q) > maxQualifier {
+			return nil, fmt.Errorf("%w: %s qualifiers are at most %d characters", ErrInvalid, request.Standard, maxQualifier)
+		}
+	}
+	return s.repo.SavePartner(ctx, orgID, request)
+}
+
+// ReceiveInterchange processes an inbound interchange. The sender must be an
+// active partner addressing the organization's interchange ID. Each purchase
+// order becomes a draft sales order; documents that cannot be mapped are
+// logged as failed without stopping the others. Interchanges that cannot be
+// parsed or attributed are logged and rejected.
+func (s *EDIService) ReceiveInterchange(ctx context.Context, orgID, userID uuid.UUID, payload []byte) (*types.InboundResult, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("%w: empty interchange", ErrInvalid)
+	}
+
+	inbound := types.Message{
+		OrganizationID: orgID,
+		Direction:      types.DirectionInbound,
+		Status:         types.MessageStatusFailed,
+		Payload:        string(payload),
+		CreatedBy:      &userID,
+	}
+	reject := func(cause error) (*types.InboundResult, error) {
+		inbound.Error = cause.Error()
+		if _, err := s.repo.SaveMessage(ctx, inbound); err != nil {
+			return nil, err
+		}
+		return nil, cause
+	}
+
+	ic, err := edi.Parse(payload)
+	if err != nil {
+		return reject(fmt.Errorf("%w: %v", ErrInvalid, err))
+	}
+	inbound.Standard = ic.Envelope.Standard
+	inbound.InterchangeControlNumber = ic.Envelope.ControlNumber
+
+	partner, err := s.repo.FindPartnerByInterchange(ctx, orgID, ic.Envelope.SenderQualifier, ic.Envelope.SenderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return reject(fmt.Errorf("%w for sender %s", ErrUnknownPartner, ic.Envelope.SenderID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	inbound.PartnerID = &partner.ID
+
+	switch {
+	case !partner.Active:
+		return reject(fmt.Errorf("%w: partner %s is inactive", ErrInvalid, partner.Name))
+	case partner.Standard != ic.Envelope.Standard:
+		return reject(fmt.Errorf("%w: partner %s exchanges %s, not %s", ErrInvalid, partner.Name, partner.Standard, ic.Envelope.Standard))
+	case ic.Envelope.ReceiverID != partner.OurInterchangeID:
+		return reject(fmt.Errorf("%w: interchange is addressed to %s", ErrInvalid, ic.Envelope.ReceiverID))
+	case len(ic.Messages) == 0:
+		return reject(fmt.Errorf("%w: interchange has no documents", ErrInvalid))
+	}
+
+	result := &types.InboundResult{Messages: make([]types.Message, 0, len(ic.Messages))}
+	for _, m := range ic.Messages {
+		logged := inbound
+		logged.ControlNumber = m.ControlNumber
+		logged.DocumentType = edi.DocumentTypeOf(ic.Envelope.Standard, m.Type)
+
+		orderID, po, err := s.receiveOrder(ctx, orgID, userID, *partner, ic.Envelope.Standard, m)
+		if po != nil {
+			logged.Reference = po.Number
+		}
+		if err != nil {
+			s.logger.Warn("Inbound EDI document failed", "partner", partner.Name, "control_number", m.ControlNumber, "error", err)
+			logged.Error = err.Error()
+		} else {
+			logged.Status = types.MessageStatusProcessed
+			logged.EntityType = types.EntitySalesOrder
+			logged.EntityID = &orderID
+		}
+
+		saved, err := s.repo.SaveMessage(ctx, logged)
+		if err != nil {
+			return nil, err
+		}
+		result.Messages = append(result.Messages, *saved)
+	}
+	return result, nil
+}
+
+func (s *EDIService) receiveOrder(ctx context.Context, orgID, userID uuid.UUID, partner types.Partner, standard edi.Standard, m edi.Message) (uuid.UUID, *edi.PurchaseOrder, error) {
+	if edi.DocumentTypeOf(standard, m.Type) != edi.DocumentPurchaseOrder {
+		return uuid.Nil, nil, fmt.Errorf("%w: inbound %s documents are not supported", ErrInvalid, m.Type)
+	}
+	po, err := edi.DecodePurchaseOrder(standard, m)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	for _, line := range po.Lines {
+		if line.Quantity <= 0 {
+			return uuid.Nil, po, fmt.Errorf("%w: line %s has no quantity", ErrInvalid, line.LineNumber)
+		}
+		if line.ProductCode == "" {
+			return uuid.Nil, po, fmt.Errorf("%w: line %s has no product code", ErrInvalid, line.LineNumber)
+		}
+	}
+	orderID, err := s.repo.CreateSalesOrder(ctx, orgID, userID, partner, *po)
+	return orderID, po, err
+}
+
+// SendShipNotice generates the ASN of a shipment that has left, addressed to
+// the partner of the customer it ships to
+func (s *EDIService) SendShipNotice(ctx context.Context, orgID, userID, shipmentID uuid.UUID) (*types.Message, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	src, err := s.repo.ShipNoticeSource(ctx, orgID, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if src.Status != "in_transit" && src.Status != "delivered" {
+		return nil, fmt.Errorf("%w: shipment is %s; ASNs are sent once it is in transit", ErrInvalid, src.Status)
+	}
+	if src.Notice.PurchaseOrder == "" {
+		return nil, fmt.Errorf("%w: shipment is not for an order with a customer PO number", ErrInvalid)
+	}
+	if len(src.Notice.Lines) == 0 {
+		return nil, fmt.Errorf("%w: shipment has no products", ErrInvalid)
+	}
+	if src.Notice.ShipDate.IsZero() {
+		src.Notice.ShipDate = s.now()
+	}
+
+	return s.send(ctx, orgID, userID, src.CustomerID, edi.DocumentShipNotice, src.Notice.ShipmentID,
+		types.EntityShipment, shipmentID, func(standard edi.Standard) edi.Message {
+			return edi.ShipNoticeMessage(standard, src.Notice)
+		})
+}
+
+// SendInvoice generates the EDI invoice of a posted customer invoice
+func (s *EDIService) SendInvoice(ctx context.Context, orgID, userID, invoiceID uuid.UUID) (*types.Message, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	src, err := s.repo.InvoiceSource(ctx, orgID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if src.MoveType != "out_invoice" {
+		return nil, fmt.Errorf("%w: only customer invoices can be sent", ErrInvalid)
+	}
+	if src.State != "posted" {
+		return nil, fmt.Errorf("%w: invoice is %s; only posted invoices can be sent", ErrInvalid, src.State)
+	}
+	if len(src.Invoice.Lines) == 0 {
+		return nil, fmt.Errorf("%w: invoice has no product lines", ErrInvalid)
+	}
+
+	return s.send(ctx, orgID, userID, src.CustomerID, edi.DocumentInvoice, src.Invoice.Number,
+		types.EntityInvoice, invoiceID, func(standard edi.Standard) edi.Message {
+			return edi.InvoiceMessage(standard, src.Invoice)
+		})
+}
+
+// send encodes a document in the standard of the customer's partner and logs
+// it as generated
+func (s *EDIService) send(ctx context.Context, orgID, userID, customerID uuid.UUID, document edi.DocumentType,
+	reference, entityType string, entityID uuid.UUID, build func(edi.Standard) edi.Message) (*types.Message, error) {
+	partner, err := s.repo.FindPartnerByContact(ctx, orgID, customerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w for the customer", ErrUnknownPartner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !partner.Active {
+		return nil, fmt.Errorf("%w: partner %s is inactive", ErrInvalid, partner.Name)
+	}
+
+	control, err := s.repo.NextControlNumber(ctx, orgID, partner.ID)
+	if err != nil {
+		return nil, err
+	}
+	env := edi.Envelope{
+		Standard:          partner.Standard,
+		SenderID:          partner.OurInterchangeID,
+		SenderQualifier:   partner.OurInterchangeQualifier,
+		ReceiverID:        partner.InterchangeID,
+		ReceiverQualifier: partner.InterchangeQualifier,
+		ControlNumber:     strconv.FormatInt(control, 10),
+		Date:              s.now().UTC(),
+		Test:              partner.Test,
+	}
+	payload, err := edi.Encode(env, []edi.Message{build(partner.Standard)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", document, err)
+	}
+
+	return s.repo.SaveMessage(ctx, types.Message{
+		OrganizationID:           orgID,
+		PartnerID:                &partner.ID,
+		Direction:                types.DirectionOutbound,
+		Standard:                 partner.Standard,
+		DocumentType:             document,
+		InterchangeControlNumber: env.ControlNumber,
+		// Outbound interchanges carry a single document
+		ControlNumber: "1",
+		Reference:     reference,
+		Status:        types.MessageStatusGenerated,
+		EntityType:    entityType,
+		EntityID:      &entityID,
+		Payload:       string(payload),
+		CreatedBy:     &userID,
+	})
+}
+
+// ListMessages returns a page of the message log, newest first
+func (s *EDIService) ListMessages(ctx context.Context, filter types.MessageFilter) ([]types.Message, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Direction != "" && filter.Direction != types.DirectionInbound && filter.Direction != types.DirectionOutbound {
+		return nil, fmt.Errorf("%w: direction must be inbound or outbound", ErrInvalid)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultMessageLimit
+	}
+	if filter.Limit > MaxMessageLimit {
+		filter.Limit = MaxMessageLimit
+	}
+	return s.repo.ListMessages(ctx, filter)
+}
+
+// GetMessage returns a logged message with its payload
+func (s *EDIService) GetMessage(ctx context.Context, orgID, id uuid.UUID) (*types.Message, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindMessage(ctx, orgID, id)
+}
+
+// MarkSent records that an outbound message was delivered to the partner,
+// e.g. by the VAN or AS2 gateway that picked it up
+func (s *EDIService) MarkSent(ctx context.Context, orgID, id uuid.UUID) (*types.Message, error) {
+	if err := s.authService.CheckPermission(ctx, "edi:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	message, err := s.repo.FindMessage(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if message.Direction != types.DirectionOutbound {
+		return nil, fmt.Errorf("%w: only outbound messages are sent", ErrInvalid)
+	}
+	if message.Status == types.MessageStatusSent {
+		return message, nil
+	}
+
+	sentAt := s.now()
+	if err := s.repo.MarkSent(ctx, orgID, id, sentAt); err != nil {
+		return nil, err
+	}
+	message.Status = types.MessageStatusSent
+	message.SentAt = &sentAt
+	return message, nil
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/edi/types"
+	"github.com/KevTiv/alieze-erp/pkg/edi"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEDIRepo struct {
+	partner    types.Partner
+	orders     map[string]edi.PurchaseOrder
+	shipment   *types.ShipNoticeSource
+	invoice    *types.InvoiceSource
+	messages   map[uuid.UUID]types.Message
+	controlNum int64
+}
+
+func newFakeEDIRepo() *fakeEDIRepo {
+	return &fakeEDIRepo{
+		partner: types.Partner{
+			ID:                   uuid.New(),
+			ContactID:            uuid.New(),
+			Name:                 "Big Retail",
+			Standard:             edi.StandardX12,
+			InterchangeID:        "BIGRETAIL",
+			InterchangeQualifier: "ZZ",
+			OurInterchangeID:     "ALIEZE",
+			Active:               true,
+		},
+		orders:   make(map[string]edi.PurchaseOrder),
+		messages: make(map[uuid.UUID]types.Message),
+	}
+}
+
+func (f *fakeEDIRepo) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	return []types.Partner{f.partner}, nil
+}
+
+func (f *fakeEDIRepo) FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	if id != f.partner.ID {
+		return nil, fmt.Errorf("EDI partner %w", repository.ErrNotFound)
+	}
+	p := f.partner
+	return &p, nil
+}
+
+func (f *fakeEDIRepo) FindPartnerByInterchange(ctx context.Context, orgID uuid.UUID, qualifier, id string) (*types.Partner, error) {
+	if id != f.partner.InterchangeID {
+		return nil, fmt.Errorf("EDI partner %w", repository.ErrNotFound)
+	}
+	p := f.partner
+	return &p, nil
+}
+
+func (f *fakeEDIRepo) FindPartnerByContact(ctx context.Context, orgID, contactID uuid.UUID) (*types.Partner, error) {
+	if contactID != f.partner.ContactID {
+		return nil, fmt.Errorf("EDI partner %w", repository.ErrNotFound)
+	}
+	p := f.partner
+	return &p, nil
+}
+
+func (f *fakeEDIRepo) SavePartner(ctx context.Context, orgID uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	f.partner.Name = request.Name
+	f.partner.InterchangeID = request.InterchangeID
+	p := f.partner
+	return &p, nil
+}
+
+func (f *fakeEDIRepo) NextControlNumber(ctx context.Context, orgID, partnerID uuid.UUID) (int64, error) {
+	f.controlNum++
+	return f.controlNum, nil
+}
+
+func (f *fakeEDIRepo) CreateSalesOrder(ctx context.Context, orgID, userID uuid.UUID, partner types.Partner, po edi.PurchaseOrder) (uuid.UUID, error) {
+	if _, ok := f.orders[po.Number]; ok {
+		return uuid.Nil, fmt.Errorf("%w: %s", repository.ErrDuplicateOrder, po.Number)
+	}
+	f.orders[po.Number] = po
+	return uuid.New(), nil
+}
+
+func (f *fakeEDIRepo) ShipNoticeSource(ctx context.Context, orgID, shipmentID uuid.UUID) (*types.ShipNoticeSource, error) {
+	if f.shipment == nil {
+		return nil, fmt.Errorf("shipment %w", repository.ErrNotFound)
+	}
+	return f.shipment, nil
+}
+
+func (f *fakeEDIRepo) InvoiceSource(ctx context.Context, orgID, invoiceID uuid.UUID) (*types.InvoiceSource, error) {
+	if f.invoice == nil {
+		return nil, fmt.Errorf("invoice %w", repository.ErrNotFound)
+	}
+	return f.invoice, nil
+}
+
+func (f *fakeEDIRepo) SaveMessage(ctx context.Context, message types.Message) (*types.Message, error) {
+	message.ID = uuid.New()
+	f.messages[message.ID] = message
+	return &message, nil
+}
+
+func (f *fakeEDIRepo) ListMessages(ctx context.Context, filter types.MessageFilter) ([]types.Message, error) {
+	var messages []types.Message
+	for _, m := range f.messages {
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+func (f *fakeEDIRepo) FindMessage(ctx context.Context, orgID, id uuid.UUID) (*types.Message, error) {
+	m, ok := f.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("EDI message %w", repository.ErrNotFound)
+	}
+	return &m, nil
+}
+
+func (f *fakeEDIRepo) MarkSent(ctx context.Context, orgID, id uuid.UUID, sentAt time.Time) error {
+	m := f.messages[id]
+	m.Status = types.MessageStatusSent
+	m.SentAt = &sentAt
+	f.messages[id] = m
+	return nil
+}
+
+func (f *fakeEDIRepo) logged(status types.MessageStatus) []types.Message {
+	var messages []types.Message
+	for _, m := range f.messages {
+		if m.Status == status {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeEDIRepo) *EDIService {
+	svc := NewEDIService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC) }
+	return svc
+}
+
+func purchaseOrders(t *testing.T, sender string, numbers ...string) []byte {
+	t.Helper()
+	var messages []edi.Message
+	for _, n := range numbers {
+		messages = append(messages, edi.Message{Type: "850", Segments: []edi.Segment{
+			edi.NewSegment("BEG", "00", "SA", n, "", "20250301"),
+			edi.NewSegment("PO1", "1", "12", "EA", "4.5", "", "BP", "CUST-1", "VP", "WID-100"),
+		}})
+	}
+	data, err := edi.Encode(edi.Envelope{Standard: edi.StandardX12, SenderID: sender, SenderQualifier: "ZZ",
+		ReceiverID: "ALIEZE", ControlNumber: "42"}, messages)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReceiveInterchange(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+
+	t.Run("creates a sales order per purchase order", func(t *testing.T) {
+		repo := newFakeEDIRepo()
+		result, err := newTestService(repo).ReceiveInterchange(ctx, orgID, userID, purchaseOrders(t, "BIGRETAIL", "PO-1", "PO-2"))
+		require.NoError(t, err)
+
+		require.Len(t, result.Messages, 2)
+		for i, m := range result.Messages {
+			assert.Equal(t, types.MessageStatusProcessed, m.Status)
+			assert.Equal(t, edi.DocumentPurchaseOrder, m.DocumentType)
+			assert.Equal(t, fmt.Sprintf("PO-%d", i+1), m.Reference)
+			assert.Equal(t, "42", m.InterchangeControlNumber)
+			assert.Equal(t, types.EntitySalesOrder, m.EntityType)
+			require.NotNil(t, m.EntityID)
+			assert.Equal(t, repo.partner.ID, *m.PartnerID)
+		}
+		assert.Equal(t, "CUST-1", repo.orders["PO-1"].Lines[0].ProductCode)
+	})
+
+	t.Run("logs documents that cannot be mapped", func(t *testing.T) {
+		repo := newFakeEDIRepo()
+		repo.orders["PO-1"] = edi.PurchaseOrder{Number: "PO-1"}
+
+		result, err := newTestService(repo).ReceiveInterchange(ctx, orgID, userID, purchaseOrders(t, "BIGRETAIL", "PO-1", "PO-2"))
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 2)
+		assert.Equal(t, types.MessageStatusFailed, result.Messages[0].Status)
+		assert.Contains(t, result.Messages[0].Error, "already received")
+		assert.Nil(t, result.Messages[0].EntityID)
+		assert.Equal(t, types.MessageStatusProcessed, result.Messages[1].Status)
+	})
+
+	rejected := []struct {
+		name    string
+		payload func(t *testing.T) []byte
+		modify  func(*types.Partner)
+		err     error
+	}{
+		{"malformed interchange", func(t *testing.T) []byte { return []byte("not edi") }, nil, ErrInvalid},
+		{"unknown sender", func(t *testing.T) []byte { return purchaseOrders(t, "STRANGER", "PO-1") }, nil, ErrUnknownPartner},
+		{"inactive partner", func(t *testing.T) []byte { return purchaseOrders(t, "BIGRETAIL", "PO-1") },
+			func(p *types.Partner) { p.Active = false }, ErrInvalid},
+		{"other standard", func(t *testing.T) []byte { return purchaseOrders(t, "BIGRETAIL", "PO-1") },
+			func(p *types.Partner) { p.Standard = edi.StandardEDIFACT }, ErrInvalid},
+		{"other receiver", func(t *testing.T) []byte { return purchaseOrders(t, "BIGRETAIL", "PO-1") },
+			func(p *types.Partner) { p.OurInterchangeID = "ALIEZE-EU" }, ErrInvalid},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeEDIRepo()
+			if tt.modify != nil {
+				tt.modify(&repo.partner)
+			}
+
+			_, err := newTestService(repo).ReceiveInterchange(ctx, orgID, userID, tt.payload(t))
+			assert.ErrorIs(t, err, tt.err)
+			assert.Empty(t, repo.orders)
+			require.Len(t, repo.logged(types.MessageStatusFailed), 1)
+			assert.Equal(t, types.DirectionInbound, repo.logged(types.MessageStatusFailed)[0].Direction)
+		})
+	}
+}
+
+func TestSendShipNotice(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID, shipmentID := uuid.New(), uuid.New(), uuid.New()
+
+	source := func(repo *fakeEDIRepo) *types.ShipNoticeSource {
+		return &types.ShipNoticeSource{
+			ShipmentID: shipmentID,
+			CustomerID: repo.partner.ContactID,
+			Status:     "in_transit",
+			Notice: edi.ShipNotice{
+				ShipmentID:    "WH/OUT/00012",
+				PurchaseOrder: "PO-1",
+				Lines:         []edi.LineItem{{LineNumber: "1", ProductCode: "CUST-1", Quantity: 12}},
+			},
+		}
+	}
+
+	t.Run("generates an ASN for the customer's partner", func(t *testing.T) {
+		repo := newFakeEDIRepo()
+		repo.shipment = source(repo)
+		svc := newTestService(repo)
+
+		first, err := svc.SendShipNotice(ctx, orgID, userID, shipmentID)
+		require.NoError(t, err)
+		second, err := svc.SendShipNotice(ctx, orgID, userID, shipmentID)
+		require.NoError(t, err)
+
+		assert.Equal(t, types.MessageStatusGenerated, first.Status)
+		assert.Equal(t, types.DirectionOutbound, first.Direction)
+		assert.Equal(t, edi.DocumentShipNotice, first.DocumentType)
+		assert.Equal(t, "WH/OUT/00012", first.Reference)
+		assert.Equal(t, shipmentID, *first.EntityID)
+		assert.Equal(t, "1", first.InterchangeControlNumber)
+		assert.Equal(t, "2", second.InterchangeControlNumber)
+
+		ic, err := edi.Parse([]byte(first.Payload))
+		require.NoError(t, err)
+		assert.Equal(t, "ALIEZE", ic.Envelope.SenderID)
+		assert.Equal(t, "BIGRETAIL", ic.Envelope.ReceiverID)
+		require.Len(t, ic.Messages, 1)
+		assert.Equal(t, "856", ic.Messages[0].Type)
+	})
+
+	invalid := []struct {
+		name   string
+		modify func(*types.ShipNoticeSource)
+		err    error
+	}{
+		{"not shipped yet", func(s *types.ShipNoticeSource) { s.Status = "scheduled" }, ErrInvalid},
+		{"no customer PO", func(s *types.ShipNoticeSource) { s.Notice.PurchaseOrder = "" }, ErrInvalid},
+		{"no products", func(s *types.ShipNoticeSource) { s.Notice.Lines = nil }, ErrInvalid},
+		{"customer without partner", func(s *types.ShipNoticeSource) { s.CustomerID = uuid.New() }, ErrUnknownPartner},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeEDIRepo()
+			repo.shipment = source(repo)
+			tt.modify(repo.shipment)
+
+			_, err := newTestService(repo).SendShipNotice(ctx, orgID, userID, shipmentID)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Empty(t, repo.messages)
+		})
+	}
+}
+
+func TestSendInvoice(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID, invoiceID := uuid.New(), uuid.New(), uuid.New()
+
+	source := func(repo *fakeEDIRepo) *types.InvoiceSource {
+		return &types.InvoiceSource{
+			InvoiceID:  invoiceID,
+			CustomerID: repo.partner.ContactID,
+			MoveType:   "out_invoice",
+			State:      "posted",
+			Invoice: edi.Invoice{
+				Number:      "INV/2025/0031",
+				Lines:       []edi.LineItem{{LineNumber: "1", ProductCode: "CUST-1", Quantity: 12, UnitPrice: 4.5}},
+				TotalAmount: 54,
+			},
+		}
+	}
+
+	t.Run("generates an EDIFACT invoice", func(t *testing.T) {
+		repo := newFakeEDIRepo()
+		repo.partner.Standard = edi.StandardEDIFACT
+		repo.invoice = source(repo)
+
+		message, err := newTestService(repo).SendInvoice(ctx, orgID, userID, invoiceID)
+		require.NoError(t, err)
+		assert.Equal(t, edi.DocumentInvoice, message.DocumentType)
+		assert.Equal(t, types.EntityInvoice, message.EntityType)
+
+		ic, err := edi.Parse([]byte(message.Payload))
+		require.NoError(t, err)
+		assert.Equal(t, "INVOIC", ic.Messages[0].Type)
+	})
+
+	invalid := []struct {
+		name   string
+		modify func(*types.InvoiceSource)
+	}{
+		{"draft invoice", func(s *types.InvoiceSource) { s.State = "draft" }},
+		{"vendor bill", func(s *types.InvoiceSource) { s.MoveType = "in_invoice" }},
+		{"no product lines", func(s *types.InvoiceSource) { s.Invoice.Lines = nil }},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeEDIRepo()
+			repo.invoice = source(repo)
+			tt.modify(repo.invoice)
+
+			_, err := newTestService(repo).SendInvoice(ctx, orgID, userID, invoiceID)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestSavePartner(t *testing.T) {
+	ctx := context.Background()
+	valid := types.PartnerRequest{
+		ContactID:        uuid.New(),
+		Name:             " Big Retail ",
+		Standard:         edi.StandardX12,
+		InterchangeID:    "BIGRETAIL",
+		OurInterchangeID: "ALIEZE",
+	}
+
+	repo := newFakeEDIRepo()
+	partner, err := newTestService(repo).SavePartner(ctx, uuid.New(), valid)
+	require.NoError(t, err)
+	assert.Equal(t, "Big Retail", partner.Name)
+
+	tests := []struct {
+		name   string
+		modify func(*types.PartnerRequest)
+	}{
+		{"no contact", func(r *types.PartnerRequest) { r.ContactID = uuid.Nil }},
+		{"unknown standard", func(r *types.PartnerRequest) { r.Standard = "xml" }},
+		{"no interchange ID", func(r *types.PartnerRequest) { r.InterchangeID = "" }},
+		{"X12 ID too long", func(r *types.PartnerRequest) { r.InterchangeID = "BIG-RETAIL-STORES-US" }},
+		{"X12 qualifier too long", func(r *types.PartnerRequest) { r.InterchangeQualifier = "ZZZ" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := newTestService(newFakeEDIRepo()).SavePartner(ctx, uuid.New(), req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestMarkSent(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakeEDIRepo()
+	svc := newTestService(repo)
+
+	outbound, _ := repo.SaveMessage(ctx, types.Message{Direction: types.DirectionOutbound, Status: types.MessageStatusGenerated})
+	inbound, _ := repo.SaveMessage(ctx, types.Message{Direction: types.DirectionInbound, Status: types.MessageStatusProcessed})
+
+	message, err := svc.MarkSent(ctx, orgID, outbound.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.MessageStatusSent, message.Status)
+	require.NotNil(t, message.SentAt)
+
+	_, err = svc.MarkSent(ctx, orgID, inbound.ID)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
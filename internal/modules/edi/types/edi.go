@@ -0,0 +1,131 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/edi"
+
+	"github.com/google/uuid"
+)
+
+// Partner is a customer exchanging EDI documents with the organization
+type Partner struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	ContactID      uuid.UUID    `json:"contact_id"`
+	Name           string       `json:"name"`
+	Standard       edi.Standard `json:"standard"`
+	// InterchangeID and InterchangeQualifier identify the partner in
+	// interchange envelopes (ISA06/ISA05 or UNB sender)
+	InterchangeID        string `json:"interchange_id"`
+	InterchangeQualifier string `json:"interchange_qualifier,omitempty"`
+	// OurInterchangeID and OurInterchangeQualifier identify the organization
+	// to this partner
+	OurInterchangeID        string    `json:"our_interchange_id"`
+	OurInterchangeQualifier string    `json:"our_interchange_qualifier,omitempty"`
+	Test                    bool      `json:"test"`
+	Active                  bool      `json:"active"`
+	LastControlNumber       int64     `json:"last_control_number"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// PartnerRequest creates or updates the partner of a contact
+type PartnerRequest struct {
+	ContactID               uuid.UUID    `json:"contact_id"`
+	Name                    string       `json:"name"`
+	Standard                edi.Standard `json:"standard"`
+	InterchangeID           string       `json:"interchange_id"`
+	InterchangeQualifier    string       `json:"interchange_qualifier"`
+	OurInterchangeID        string       `json:"our_interchange_id"`
+	OurInterchangeQualifier string       `json:"our_interchange_qualifier"`
+	Test                    bool         `json:"test"`
+	Active                  *bool        `json:"active,omitempty"`
+}
+
+// Direction tells whether a message was received or generated
+type Direction string
+
+const (
+	DirectionInbound  Direction = "inbound"
+	DirectionOutbound Direction = "outbound"
+)
+
+// MessageStatus is the processing state of a logged message. Inbound
+// messages are processed or failed; outbound messages are generated and then
+// marked sent once delivered to the partner.
+type MessageStatus string
+
+const (
+	MessageStatusProcessed MessageStatus = "processed"
+	MessageStatusFailed    MessageStatus = "failed"
+	MessageStatusGenerated MessageStatus = "generated"
+	MessageStatusSent      MessageStatus = "sent"
+)
+
+// Entity types a message maps to
+const (
+	EntitySalesOrder = "sales_order"
+	EntityShipment   = "delivery_shipment"
+	EntityInvoice    = "invoice"
+)
+
+// Message is an entry of the EDI message log
+type Message struct {
+	ID                       uuid.UUID        `json:"id"`
+	OrganizationID           uuid.UUID        `json:"organization_id"`
+	PartnerID                *uuid.UUID       `json:"partner_id,omitempty"`
+	Direction                Direction        `json:"direction"`
+	Standard                 edi.Standard     `json:"standard,omitempty"`
+	DocumentType             edi.DocumentType `json:"document_type,omitempty"`
+	InterchangeControlNumber string           `json:"interchange_control_number,omitempty"`
+	ControlNumber            string           `json:"control_number,omitempty"`
+	// Reference is the business number of the document: the customer's PO
+	// number, the shipment or the invoice number
+	Reference  string        `json:"reference,omitempty"`
+	Status     MessageStatus `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	EntityType string        `json:"entity_type,omitempty"`
+	EntityID   *uuid.UUID    `json:"entity_id,omitempty"`
+	Payload    string        `json:"-"`
+	CreatedBy  *uuid.UUID    `json:"created_by,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	SentAt     *time.Time    `json:"sent_at,omitempty"`
+}
+
+// MessageFilter narrows the message log
+type MessageFilter struct {
+	OrganizationID uuid.UUID
+	PartnerID      *uuid.UUID
+	Direction      Direction
+	Status         MessageStatus
+	DocumentType   edi.DocumentType
+	EntityID       *uuid.UUID
+	Limit          int
+	Offset         int
+}
+
+// InboundResult reports what an inbound interchange produced, one message
+// per document
+type InboundResult struct {
+	Messages []Message `json:"messages"`
+}
+
+// ShipNoticeSource is a delivery shipment to announce in an ASN
+type ShipNoticeSource struct {
+	ShipmentID uuid.UUID
+	// CustomerID is the contact the goods are shipped to; its partner, or its
+	// parent's, receives the ASN
+	CustomerID uuid.UUID
+	Status     string
+	Notice     edi.ShipNotice
+}
+
+// InvoiceSource is a customer invoice to send
+type InvoiceSource struct {
+	InvoiceID  uuid.UUID
+	CustomerID uuid.UUID
+	MoveType   string
+	State      string
+	Invoice    edi.Invoice
+}
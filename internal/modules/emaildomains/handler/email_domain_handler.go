@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EmailDomainHandler handles the administration and verification of an
+// organization's sending and tracking domains
+type EmailDomainHandler struct {
+	service *service.EmailDomainService
+}
+
+func NewEmailDomainHandler(service *service.EmailDomainService) *EmailDomainHandler {
+	return &EmailDomainHandler{service: service}
+}
+
+func (h *EmailDomainHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/email-domains", h.ListDomains)
+	router.POST("/api/v1/email-domains", h.CreateDomain)
+	router.GET("/api/v1/email-domains/:id", h.GetDomain)
+	router.DELETE("/api/v1/email-domains/:id", h.DeleteDomain)
+	router.PUT("/api/v1/email-domains/:id/reply-to", h.UpdateReplyTo)
+	router.POST("/api/v1/email-domains/:id/verify", h.VerifyDomain)
+}
+
+// ListDomains handles GET /api/v1/email-domains
+func (h *EmailDomainHandler) ListDomains(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	domains, err := h.service.ListDomains(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domains)
+}
+
+// CreateDomain handles POST /api/v1/email-domains. The response lists the
+// DNS records to publish before the domain can be verified.
+func (h *EmailDomainHandler) CreateDomain(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.DomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.service.CreateDomain(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, domain)
+}
+
+// GetDomain handles GET /api/v1/email-domains/:id
+func (h *EmailDomainHandler) GetDomain(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.service.GetDomain(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain)
+}
+
+// DeleteDomain handles DELETE /api/v1/email-domains/:id
+func (h *EmailDomainHandler) DeleteDomain(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDomain(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateReplyTo handles PUT /api/v1/email-domains/:id/reply-to
+func (h *EmailDomainHandler) UpdateReplyTo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ReplyToRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.service.UpdateReplyTo(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain)
+}
+
+// VerifyDomain handles POST /api/v1/email-domains/:id/verify. It checks the
+// domain's records immediately and returns the verification status with the
+// outcome of each record.
+func (h *EmailDomainHandler) VerifyDomain(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	domain, err := h.service.VerifyDomain(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeEmailDomainError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domain)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEmailDomainError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeVerifyDomains = "email_domains.verify"
+
+// VerifyJobHandler handles queued domain verification passes
+type VerifyJobHandler struct {
+	domainService *service.EmailDomainService
+	logger        *slog.Logger
+}
+
+func NewVerifyJobHandler(domainService *service.EmailDomainService, logger *slog.Logger) *VerifyJobHandler {
+	return &VerifyJobHandler{
+		domainService: domainService,
+		logger:        logger,
+	}
+}
+
+// Handle checks the DNS records of the domains due for verification
+func (h *VerifyJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.domainService.VerifyDue(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify email domains: %w", err)
+	}
+	if run.Checked > 0 {
+		h.logger.Info("Email domains checked", "checked", run.Checked, "verified", run.Verified, "failed", run.Failed)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *VerifyJobHandler) JobType() string {
+	return JobTypeVerifyDomains
+}
+
+// Scheduler runs verification passes on a fixed interval, so domains are
+// verified soon after their records are published without the organization
+// asking
+type Scheduler struct {
+	handler     *VerifyJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *VerifyJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs verification passes every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeVerifyDomains, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeVerifyDomains, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled email domain verification failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule email domain verification", "error", err)
+	}
+}
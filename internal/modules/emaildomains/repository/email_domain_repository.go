@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a domain or inbox does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when the organization already added the
+	// domain, or another organization verified it
+	ErrDuplicate = errors.New("already exists")
+)
+
+// EmailDomainRepo defines the interface for email domain repository operations
+type EmailDomainRepo interface {
+	ListDomains(ctx context.Context, orgID uuid.UUID) ([]types.Domain, error)
+	FindDomain(ctx context.Context, orgID, id uuid.UUID) (*types.Domain, error)
+	CreateDomain(ctx context.Context, domain types.Domain) (*types.Domain, error)
+	UpdateReplyTo(ctx context.Context, orgID, id uuid.UUID, inboxID *uuid.UUID) (*types.Domain, error)
+	// SaveVerification records the outcome of a check of the domain's records
+	SaveVerification(ctx context.Context, domain types.Domain) (*types.Domain, error)
+	DeleteDomain(ctx context.Context, orgID, id uuid.UUID) error
+	// FindInboxAddress returns the address of an active inbox of the organization
+	FindInboxAddress(ctx context.Context, orgID, inboxID uuid.UUID) (string, error)
+	// ListForVerification returns up to limit domains due for a check:
+	// pending domains last checked before pendingBefore, others last
+	// checked before checkedBefore
+	ListForVerification(ctx context.Context, pendingBefore, checkedBefore time.Time, limit int) ([]types.Domain, error)
+	// FindVerified returns the organization's most recently verified domain
+	// of the kind
+	FindVerified(ctx context.Context, orgID uuid.UUID, kind types.DomainKind) (*types.Domain, error)
+	FindVerifiedByHost(ctx context.Context, host string, kind types.DomainKind) (*types.Domain, error)
+}
+
+// EmailDomainRepository stores organizations' sending and tracking domains
+type EmailDomainRepository struct {
+	db *sql.DB
+}
+
+// Ensure EmailDomainRepository implements EmailDomainRepo interface
+var _ EmailDomainRepo = &EmailDomainRepository{}
+
+func NewEmailDomainRepository(db *sql.DB) *EmailDomainRepository {
+	return &EmailDomainRepository{db: db}
+}
+
+const domainColumns = `d.id, d.organization_id, d.domain, d.kind, d.status, COALESCE(d.dkim_selector, ''),
+	COALESCE(d.dkim_public_key, ''), COALESCE(d.dkim_private_key, ''), d.reply_to_inbox_id, i.address,
+	d.records, d.last_checked_at, d.verified_at, d.created_at, d.updated_at, d.created_by`
+
+const domainFrom = ` FROM email_domains d
+	LEFT JOIN lead_email_inboxes i ON i.id = d.reply_to_inbox_id AND i.active`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDomain(row rowScanner) (*types.Domain, error) {
+	var d types.Domain
+	var records []byte
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.Domain, &d.Kind, &d.Status, &d.DKIMSelector,
+		&d.DKIMPublicKey, &d.DKIMPrivateKey, &d.ReplyToInboxID, &d.ReplyTo,
+		&records, &d.LastCheckedAt, &d.VerifiedAt, &d.CreatedAt, &d.UpdatedAt, &d.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(records, &d.Records); err != nil {
+		return nil, fmt.Errorf("invalid domain records: %w", err)
+	}
+	return &d, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (r *EmailDomainRepository) queryDomains(ctx context.Context, query string, args ...interface{}) ([]types.Domain, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []types.Domain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email domain: %w", err)
+		}
+		domains = append(domains, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email domains: %w", err)
+	}
+	return domains, nil
+}
+
+func (r *EmailDomainRepository) findOne(ctx context.Context, query string, args ...interface{}) (*types.Domain, error) {
+	d, err := scanDomain(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("email domain %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find email domain: %w", err)
+	}
+	return d, nil
+}
+
+func (r *EmailDomainRepository) ListDomains(ctx context.Context, orgID uuid.UUID) ([]types.Domain, error) {
+	return r.queryDomains(ctx, `SELECT `+domainColumns+domainFrom+`
+		WHERE d.organization_id = $1
+		ORDER BY d.kind, d.domain`, orgID)
+}
+
+func (r *EmailDomainRepository) FindDomain(ctx context.Context, orgID, id uuid.UUID) (*types.Domain, error) {
+	return r.findOne(ctx, `SELECT `+domainColumns+domainFrom+`
+		WHERE d.id = $1 AND d.organization_id = $2`, id, orgID)
+}
+
+func (r *EmailDomainRepository) CreateDomain(ctx context.Context, domain types.Domain) (*types.Domain, error) {
+	records, err := json.Marshal(domain.Records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal domain records: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO email_domains (
+			id, organization_id, domain, kind, status, dkim_selector, dkim_public_key, dkim_private_key,
+			reply_to_inbox_id, records, created_at, updated_at, created_by
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $11, $12)`,
+		domain.ID, domain.OrganizationID, domain.Domain, domain.Kind, domain.Status, domain.DKIMSelector,
+		domain.DKIMPublicKey, domain.DKIMPrivateKey, domain.ReplyToInboxID, string(records),
+		domain.CreatedAt, domain.CreatedBy,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("email domain %s %w", domain.Domain, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create email domain: %w", err)
+	}
+	return r.FindDomain(ctx, domain.OrganizationID, domain.ID)
+}
+
+func (r *EmailDomainRepository) UpdateReplyTo(ctx context.Context, orgID, id uuid.UUID, inboxID *uuid.UUID) (*types.Domain, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE email_domains SET reply_to_inbox_id = $1, updated_at = NOW()
+		WHERE id = $2 AND organization_id = $3 AND kind = 'sending'`,
+		inboxID, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reply-to inbox: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reply-to inbox: %w", err)
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("sending domain %w", ErrNotFound)
+	}
+	return r.FindDomain(ctx, orgID, id)
+}
+
+func (r *EmailDomainRepository) SaveVerification(ctx context.Context, domain types.Domain) (*types.Domain, error) {
+	records, err := json.Marshal(domain.Records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal domain records: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE email_domains SET status = $1, records = $2, last_checked_at = $3, verified_at = $4, updated_at = $3
+		WHERE id = $5 AND organization_id = $6`,
+		domain.Status, string(records), domain.LastCheckedAt, domain.VerifiedAt, domain.ID, domain.OrganizationID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("email domain %s verified by another organization: %w", domain.Domain, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to save domain verification: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save domain verification: %w", err)
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("email domain %w", ErrNotFound)
+	}
+	return r.FindDomain(ctx, domain.OrganizationID, domain.ID)
+}
+
+func (r *EmailDomainRepository) DeleteDomain(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM email_domains WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete email domain: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete email domain: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("email domain %w", ErrNotFound)
+	}
+	return nil
+}
+
+func (r *EmailDomainRepository) FindInboxAddress(ctx context.Context, orgID, inboxID uuid.UUID) (string, error) {
+	var address sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT address FROM lead_email_inboxes
+		WHERE id = $1 AND organization_id = $2 AND active`,
+		inboxID, orgID,
+	).Scan(&address)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("inbox %w", ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to find inbox: %w", err)
+	}
+	return address.String, nil
+}
+
+func (r *EmailDomainRepository) ListForVerification(ctx context.Context, pendingBefore, checkedBefore time.Time, limit int) ([]types.Domain, error) {
+	return r.queryDomains(ctx, `SELECT `+domainColumns+domainFrom+`
+		WHERE (d.status = 'pending' AND (d.last_checked_at IS NULL OR d.last_checked_at < $1))
+			OR (d.status <> 'pending' AND d.last_checked_at < $2)
+		ORDER BY d.last_checked_at NULLS FIRST
+		LIMIT $3`, pendingBefore, checkedBefore, limit)
+}
+
+func (r *EmailDomainRepository) FindVerified(ctx context.Context, orgID uuid.UUID, kind types.DomainKind) (*types.Domain, error) {
+	return r.findOne(ctx, `SELECT `+domainColumns+domainFrom+`
+		WHERE d.organization_id = $1 AND d.kind = $2 AND d.status = 'verified'
+		ORDER BY d.verified_at DESC
+		LIMIT 1`, orgID, kind)
+}
+
+func (r *EmailDomainRepository) FindVerifiedByHost(ctx context.Context, host string, kind types.DomainKind) (*types.Domain, error) {
+	return r.findOne(ctx, `SELECT `+domainColumns+domainFrom+`
+		WHERE d.domain = $1 AND d.kind = $2 AND d.status = 'verified'`, host, kind)
+}
@@ -0,0 +1,558 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// VerifyInterval is how often pending domains are checked
+	VerifyInterval = time.Hour
+	// RecheckInterval is how often verified and failed domains are checked
+	// again, so that removed records are noticed and fixed ones recovered
+	RecheckInterval = 24 * time.Hour
+	// PendingWindow is how long a new domain's records may take to appear
+	// before the domain fails verification
+	PendingWindow = 72 * time.Hour
+	// verifyBatchSize bounds the domains checked per query
+	verifyBatchSize = 100
+	// dkimKeyBits is the size of generated DKIM keys
+	dkimKeyBits = 2048
+	// DefaultSPFInclude is the SPF mechanism authorizing the platform's mail
+	// servers to send for a domain
+	DefaultSPFInclude = "include:spf.alieze-erp.com"
+	// DefaultTrackingTarget is the host tracking domains point to
+	DefaultTrackingTarget = "tracking.alieze-erp.com"
+	// EventDomainVerified is published when a domain passes verification
+	EventDomainVerified = "email_domain.verified"
+	// EventDomainFailed is published when a domain fails verification
+	EventDomainFailed = "email_domain.failed"
+)
+
+// ErrInvalid wraps validation failures of domain requests
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the email domain service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Resolver looks up the DNS records domains are verified with;
+// net.DefaultResolver implements it
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// EmailDomainService manages the domains organizations send email from and
+// serve tracking pages on, and verifies the DNS records proving they
+// control them
+type EmailDomainService struct {
+	repo           repository.EmailDomainRepo
+	authService    AuthService
+	eventBus       *events.Bus
+	resolver       Resolver
+	logger         *slog.Logger
+	now            func() time.Time
+	spfInclude     string
+	trackingTarget string
+}
+
+func NewEmailDomainService(repo repository.EmailDomainRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *EmailDomainService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EmailDomainService{
+		repo:           repo,
+		authService:    authService,
+		eventBus:       eventBus,
+		resolver:       net.DefaultResolver,
+		logger:         logger,
+		now:            time.Now,
+		spfInclude:     DefaultSPFInclude,
+		trackingTarget: DefaultTrackingTarget,
+	}
+}
+
+// SetDNSTargets sets the SPF mechanism sending domains must publish and the
+// host tracking domains must point to; empty values keep the defaults
+func (s *EmailDomainService) SetDNSTargets(spfInclude, trackingTarget string) {
+	if spfInclude != "" {
+		s.spfInclude = spfInclude
+	}
+	if trackingTarget != "" {
+		s.trackingTarget = strings.TrimSuffix(strings.ToLower(trackingTarget), ".")
+	}
+}
+
+// SetResolver replaces the DNS resolver records are looked up with
+func (s *EmailDomainService) SetResolver(resolver Resolver) {
+	s.resolver = resolver
+}
+
+func (s *EmailDomainService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish email domain event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListDomains returns the organization's domains with their verification status
+func (s *EmailDomainService) ListDomains(ctx context.Context, orgID uuid.UUID) ([]types.Domain, error) {
+	if err := s.authService.CheckPermission(ctx, "email_domains:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListDomains(ctx, orgID)
+}
+
+// GetDomain returns a domain with the records to publish and the outcome of
+// their last check
+func (s *EmailDomainService) GetDomain(ctx context.Context, orgID, id uuid.UUID) (*types.Domain, error) {
+	if err := s.authService.CheckPermission(ctx, "email_domains:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDomain(ctx, orgID, id)
+}
+
+// CreateDomain adds a domain pending verification. Sending domains get a
+// new DKIM key pair whose public key the organization publishes.
+func (s *EmailDomainService) CreateDomain(ctx context.Context, orgID, userID uuid.UUID, req types.DomainRequest) (*types.Domain, error) {
+	if err := s.authService.CheckPermission(ctx, "email_domains:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	name, err := normalizeDomain(req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: kind must be sending or tracking", ErrInvalid)
+	}
+
+	domain := types.Domain{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Domain:         name,
+		Kind:           req.Kind,
+		Status:         types.DomainPending,
+		CreatedAt:      s.now(),
+		CreatedBy:      &userID,
+	}
+	if req.ReplyToInboxID != nil {
+		if req.Kind != types.DomainSending {
+			return nil, fmt.Errorf("%w: only sending domains route replies", ErrInvalid)
+		}
+		if err := s.checkReplyInbox(ctx, orgID, *req.ReplyToInboxID); err != nil {
+			return nil, err
+		}
+		domain.ReplyToInboxID = req.ReplyToInboxID
+	}
+	if req.Kind == types.DomainSending {
+		if err := generateDKIMKey(&domain); err != nil {
+			return nil, err
+		}
+	}
+	domain.Records = s.expectedRecords(domain)
+
+	created, err := s.repo.CreateDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Email domain added", "organization_id", orgID, "domain", created.Domain, "kind", created.Kind)
+	return created, nil
+}
+
+// UpdateReplyTo routes replies to mail sent from a sending domain to a CRM
+// inbound mailbox, where they are threaded onto their leads
+func (s *EmailDomainService) UpdateReplyTo(ctx context.Context, orgID, id uuid.UUID, req types.ReplyToRequest) (*types.Domain, error) {
+	if err := s.authService.CheckPermission(ctx, "email_domains:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if req.ReplyToInboxID != nil {
+		if err := s.checkReplyInbox(ctx, orgID, *req.ReplyToInboxID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.UpdateReplyTo(ctx, orgID, id, req.ReplyToInboxID)
+}
+
+// checkReplyInbox ensures replies can be routed to the inbox: it must be an
+// active inbox of the organization with an address to reply to
+func (s *EmailDomainService) checkReplyInbox(ctx context.Context, orgID, inboxID uuid.UUID) error {
+	address, err := s.repo.FindInboxAddress(ctx, orgID, inboxID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: reply-to inbox does not exist or is inactive", ErrInvalid)
+	}
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		return fmt.Errorf("%w: reply-to inbox has no address", ErrInvalid)
+	}
+	return nil
+}
+
+// DeleteDomain removes a domain; mail is sent from the default sender again
+func (s *EmailDomainService) DeleteDomain(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "email_domains:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteDomain(ctx, orgID, id)
+}
+
+// VerifyDomain checks a domain's DNS records now rather than waiting for
+// the verification worker
+func (s *EmailDomainService) VerifyDomain(ctx context.Context, orgID, id uuid.UUID) (*types.Domain, error) {
+	if err := s.authService.CheckPermission(ctx, "email_domains:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	domain, err := s.repo.FindDomain(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.verify(ctx, *domain)
+}
+
+// VerifyDue checks pending domains every VerifyInterval and all others every
+// RecheckInterval
+func (s *EmailDomainService) VerifyDue(ctx context.Context) (*types.VerificationRun, error) {
+	now := s.now()
+	run := &types.VerificationRun{}
+	for {
+		domains, err := s.repo.ListForVerification(ctx, now.Add(-VerifyInterval), now.Add(-RecheckInterval), verifyBatchSize)
+		if err != nil {
+			return run, err
+		}
+		saved := 0
+		for _, domain := range domains {
+			checked, err := s.verify(ctx, domain)
+			if err != nil {
+				s.logger.Warn("Failed to verify email domain", "domain_id", domain.ID, "domain", domain.Domain, "error", err)
+				continue
+			}
+			saved++
+			run.Checked++
+			switch {
+			case checked.Status == types.DomainVerified && domain.Status != types.DomainVerified:
+				run.Verified++
+			case checked.Status == types.DomainFailed && domain.Status != types.DomainFailed:
+				run.Failed++
+			}
+		}
+		// Domains that failed to save are still due; stop rather than
+		// fetching them again
+		if len(domains) < verifyBatchSize || saved == 0 {
+			return run, nil
+		}
+	}
+}
+
+// verify checks the domain's records and records the outcome. A domain is
+// verified once every record is found; a verified domain that loses a
+// record, or a pending domain past PendingWindow, fails.
+func (s *EmailDomainService) verify(ctx context.Context, domain types.Domain) (*types.Domain, error) {
+	now := s.now()
+	previous := domain.Status
+	domain.Records = s.checkRecords(ctx, domain)
+	domain.LastCheckedAt = &now
+
+	verified := true
+	for _, record := range domain.Records {
+		verified = verified && record.Verified
+	}
+	if verified {
+		owner, err := s.repo.FindVerifiedByHost(ctx, domain.Domain, domain.Kind)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if owner != nil && owner.ID != domain.ID {
+			verified = false
+			for i := range domain.Records {
+				domain.Records[i].Verified = false
+				domain.Records[i].Error = "domain is verified by another organization"
+			}
+		}
+	}
+
+	switch {
+	case verified:
+		domain.Status = types.DomainVerified
+		if previous != types.DomainVerified || domain.VerifiedAt == nil {
+			domain.VerifiedAt = &now
+		}
+	case previous == types.DomainPending && now.Sub(domain.CreatedAt) <= PendingWindow:
+		domain.VerifiedAt = nil
+	default:
+		domain.Status = types.DomainFailed
+		domain.VerifiedAt = nil
+	}
+
+	saved, err := s.repo.SaveVerification(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.Status != previous {
+		event := EventDomainVerified
+		if saved.Status == types.DomainFailed {
+			event = EventDomainFailed
+		}
+		s.logger.Info("Email domain verification changed", "domain", saved.Domain, "kind", saved.Kind, "status", saved.Status)
+		s.publishEvent(ctx, event, map[string]interface{}{
+			"organization_id": saved.OrganizationID,
+			"domain_id":       saved.ID,
+			"domain":          saved.Domain,
+			"kind":            saved.Kind,
+			"status":          saved.Status,
+		})
+	}
+	return saved, nil
+}
+
+// expectedRecords lists the records the organization must publish for the
+// domain
+func (s *EmailDomainService) expectedRecords(domain types.Domain) []types.DNSRecord {
+	if domain.Kind == types.DomainTracking {
+		return []types.DNSRecord{{
+			Purpose: types.RecordTracking,
+			Type:    "CNAME",
+			Name:    domain.Domain,
+			Value:   s.trackingTarget,
+		}}
+	}
+	return []types.DNSRecord{
+		{
+			Purpose: types.RecordDKIM,
+			Type:    "TXT",
+			Name:    domain.DKIMSelector + "._domainkey." + domain.Domain,
+			Value:   "v=DKIM1; k=rsa; p=" + domain.DKIMPublicKey,
+		},
+		{
+			Purpose: types.RecordSPF,
+			Type:    "TXT",
+			Name:    domain.Domain,
+			Value:   s.spfInclude,
+		},
+	}
+}
+
+// checkRecords looks up each expected record of the domain
+func (s *EmailDomainService) checkRecords(ctx context.Context, domain types.Domain) []types.DNSRecord {
+	records := s.expectedRecords(domain)
+	for i := range records {
+		record := &records[i]
+		var err error
+		switch record.Purpose {
+		case types.RecordDKIM:
+			err = s.checkDKIM(ctx, record.Name, domain.DKIMPublicKey)
+		case types.RecordSPF:
+			err = s.checkSPF(ctx, record.Name, record.Value)
+		case types.RecordTracking:
+			err = s.checkCNAME(ctx, record.Name, record.Value)
+		}
+		record.Verified = err == nil
+		if err != nil {
+			record.Error = err.Error()
+		}
+	}
+	return records
+}
+
+// checkDKIM finds a DKIM record publishing the public key
+func (s *EmailDomainService) checkDKIM(ctx context.Context, name, publicKey string) error {
+	txts, err := s.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return lookupError("TXT", name, err)
+	}
+	for _, txt := range txts {
+		tags := dkimTags(txt)
+		if tags["p"] == publicKey && (tags["k"] == "" || strings.EqualFold(tags["k"], "rsa")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no DKIM record at %s carries the domain's public key", name)
+}
+
+// checkSPF finds the domain's SPF record and the mechanism in it. RFC 7208
+// allows a single SPF record per domain.
+func (s *EmailDomainService) checkSPF(ctx context.Context, name, mechanism string) error {
+	txts, err := s.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return lookupError("TXT", name, err)
+	}
+	var spf []string
+	for _, txt := range txts {
+		fields := strings.Fields(strings.ToLower(txt))
+		if len(fields) == 0 || fields[0] != "v=spf1" {
+			continue
+		}
+		if spf != nil {
+			return fmt.Errorf("%s publishes more than one SPF record", name)
+		}
+		spf = fields
+	}
+	if spf == nil {
+		return fmt.Errorf("no SPF record found at %s", name)
+	}
+	for _, field := range spf[1:] {
+		if strings.TrimPrefix(field, "+") == strings.ToLower(mechanism) {
+			return nil
+		}
+	}
+	return fmt.Errorf("SPF record of %s does not contain %s", name, mechanism)
+}
+
+// checkCNAME checks that the host is an alias of the target
+func (s *EmailDomainService) checkCNAME(ctx context.Context, host, target string) error {
+	cname, err := s.resolver.LookupCNAME(ctx, host)
+	if err != nil {
+		return lookupError("CNAME", host, err)
+	}
+	cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+	switch cname {
+	case target:
+		return nil
+	case host, "":
+		return fmt.Errorf("no CNAME record found at %s", host)
+	}
+	return fmt.Errorf("%s points to %s instead of %s", host, cname, target)
+}
+
+func lookupError(recordType, name string, err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return fmt.Errorf("no %s record found at %s", recordType, name)
+	}
+	return fmt.Errorf("DNS lookup of %s failed: %w", name, err)
+}
+
+// dkimTags parses the tag=value list of a DKIM record. Whitespace is
+// insignificant in values, so it is removed.
+func dkimTags(record string) map[string]string {
+	tags := map[string]string{}
+	for _, part := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(name))] = strings.Join(strings.Fields(value), "")
+	}
+	return tags
+}
+
+// generateDKIMKey gives a sending domain a new RSA key pair under a random
+// selector, so a replaced domain never reuses a published key
+func generateDKIMKey(domain *types.Domain) error {
+	key, err := rsa.GenerateKey(rand.Reader, dkimKeyBits)
+	if err != nil {
+		return fmt.Errorf("failed to generate DKIM key: %w", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode DKIM public key: %w", err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode DKIM private key: %w", err)
+	}
+	selector := make([]byte, 4)
+	if _, err := rand.Read(selector); err != nil {
+		return fmt.Errorf("failed to generate DKIM selector: %w", err)
+	}
+
+	domain.DKIMSelector = "erp" + hex.EncodeToString(selector)
+	domain.DKIMPublicKey = base64.StdEncoding.EncodeToString(public)
+	domain.DKIMPrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+	return nil
+}
+
+// normalizeDomain lowercases a domain name and checks that it is a fully
+// qualified host name
+func normalizeDomain(domain string) (string, error) {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if name == "" {
+		return "", fmt.Errorf("%w: domain is required", ErrInvalid)
+	}
+	if len(name) > 253 || net.ParseIP(name) != nil || !strings.Contains(name, ".") {
+		return "", fmt.Errorf("%w: %q is not a domain name", ErrInvalid, domain)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("%w: %q is not a domain name", ErrInvalid, domain)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", fmt.Errorf("%w: %q is not a domain name", ErrInvalid, domain)
+			}
+		}
+	}
+	return name, nil
+}
+
+// Sender returns the identity the organization's outgoing mail is sent
+// with: its most recently verified sending domain, signed with the domain's
+// DKIM key, with replies routed to its reply-to inbox. It returns
+// repository.ErrNotFound when the organization has no verified sending
+// domain, and mail goes out from the default sender.
+func (s *EmailDomainService) Sender(ctx context.Context, orgID uuid.UUID) (*types.Sender, error) {
+	domain, err := s.repo.FindVerified(ctx, orgID, types.DomainSending)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Sender{
+		OrganizationID: orgID,
+		Domain:         domain.Domain,
+		DKIMSelector:   domain.DKIMSelector,
+		DKIMPrivateKey: domain.DKIMPrivateKey,
+		ReplyTo:        domain.ReplyTo,
+	}, nil
+}
+
+// TrackingURL returns the absolute URL of a public path on the
+// organization's verified tracking domain. It returns
+// repository.ErrNotFound when the organization has none, and links use the
+// platform's host.
+func (s *EmailDomainService) TrackingURL(ctx context.Context, orgID uuid.UUID, path string) (string, error) {
+	domain, err := s.repo.FindVerified(ctx, orgID, types.DomainTracking)
+	if err != nil {
+		return "", err
+	}
+	return "https://" + domain.Domain + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// OrganizationForHost returns the organization a request's Host belongs to
+// when it is a verified tracking domain, so public pages served on it can
+// be branded and scoped to the organization
+func (s *EmailDomainService) OrganizationForHost(ctx context.Context, host string) (uuid.UUID, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	name, err := normalizeDomain(host)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("tracking domain %w", repository.ErrNotFound)
+	}
+	domain, err := s.repo.FindVerifiedByHost(ctx, name, types.DomainTracking)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return domain.OrganizationID, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/emaildomains/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEmailDomainRepo struct {
+	domains map[uuid.UUID]types.Domain
+	inboxes map[uuid.UUID]string
+}
+
+func newFakeEmailDomainRepo() *fakeEmailDomainRepo {
+	return &fakeEmailDomainRepo{
+		domains: make(map[uuid.UUID]types.Domain),
+		inboxes: make(map[uuid.UUID]string),
+	}
+}
+
+func (f *fakeEmailDomainRepo) ListDomains(ctx context.Context, orgID uuid.UUID) ([]types.Domain, error) {
+	var domains []types.Domain
+	for _, d := range f.domains {
+		if d.OrganizationID == orgID {
+			domains = append(domains, d)
+		}
+	}
+	return domains, nil
+}
+
+func (f *fakeEmailDomainRepo) FindDomain(ctx context.Context, orgID, id uuid.UUID) (*types.Domain, error) {
+	d, ok := f.domains[id]
+	if !ok || d.OrganizationID != orgID {
+		return nil, fmt.Errorf("email domain %w", repository.ErrNotFound)
+	}
+	if d.ReplyToInboxID != nil {
+		address := f.inboxes[*d.ReplyToInboxID]
+		d.ReplyTo = &address
+	}
+	return &d, nil
+}
+
+func (f *fakeEmailDomainRepo) CreateDomain(ctx context.Context, domain types.Domain) (*types.Domain, error) {
+	f.domains[domain.ID] = domain
+	return f.FindDomain(ctx, domain.OrganizationID, domain.ID)
+}
+
+func (f *fakeEmailDomainRepo) UpdateReplyTo(ctx context.Context, orgID, id uuid.UUID, inboxID *uuid.UUID) (*types.Domain, error) {
+	d, ok := f.domains[id]
+	if !ok || d.OrganizationID != orgID || d.Kind != types.DomainSending {
+		return nil, fmt.Errorf("sending domain %w", repository.ErrNotFound)
+	}
+	d.ReplyToInboxID = inboxID
+	f.domains[id] = d
+	return f.FindDomain(ctx, orgID, id)
+}
+
+func (f *fakeEmailDomainRepo) SaveVerification(ctx context.Context, domain types.Domain) (*types.Domain, error) {
+	f.domains[domain.ID] = domain
+	return f.FindDomain(ctx, domain.OrganizationID, domain.ID)
+}
+
+func (f *fakeEmailDomainRepo) DeleteDomain(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.domains, id)
+	return nil
+}
+
+func (f *fakeEmailDomainRepo) FindInboxAddress(ctx context.Context, orgID, inboxID uuid.UUID) (string, error) {
+	address, ok := f.inboxes[inboxID]
+	if !ok {
+		return "", fmt.Errorf("inbox %w", repository.ErrNotFound)
+	}
+	return address, nil
+}
+
+func (f *fakeEmailDomainRepo) ListForVerification(ctx context.Context, pendingBefore, checkedBefore time.Time, limit int) ([]types.Domain, error) {
+	var domains []types.Domain
+	for _, d := range f.domains {
+		before := checkedBefore
+		if d.Status == types.DomainPending {
+			before = pendingBefore
+		}
+		if d.LastCheckedAt == nil || d.LastCheckedAt.Before(before) {
+			domains = append(domains, d)
+		}
+	}
+	return domains, nil
+}
+
+func (f *fakeEmailDomainRepo) FindVerified(ctx context.Context, orgID uuid.UUID, kind types.DomainKind) (*types.Domain, error) {
+	for _, d := range f.domains {
+		if d.OrganizationID == orgID && d.Kind == kind && d.Status == types.DomainVerified {
+			return f.FindDomain(ctx, orgID, d.ID)
+		}
+	}
+	return nil, fmt.Errorf("email domain %w", repository.ErrNotFound)
+}
+
+func (f *fakeEmailDomainRepo) FindVerifiedByHost(ctx context.Context, host string, kind types.DomainKind) (*types.Domain, error) {
+	for _, d := range f.domains {
+		if d.Domain == host && d.Kind == kind && d.Status == types.DomainVerified {
+			return f.FindDomain(ctx, d.OrganizationID, d.ID)
+		}
+	}
+	return nil, fmt.Errorf("email domain %w", repository.ErrNotFound)
+}
+
+type fakeResolver struct {
+	txt   map[string][]string
+	cname map[string]string
+}
+
+func (r *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txts, ok := r.txt[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return txts, nil
+}
+
+func (r *fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	cname, ok := r.cname[host]
+	if !ok {
+		return host + ".", nil
+	}
+	return cname, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeEmailDomainRepo, resolver *fakeResolver, now time.Time) *EmailDomainService {
+	svc := NewEmailDomainService(repo, allowAll{}, nil, nil)
+	svc.SetResolver(resolver)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestSendingDomainVerifies(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	repo := newFakeEmailDomainRepo()
+	resolver := &fakeResolver{txt: map[string][]string{}}
+	svc := newTestService(repo, resolver, now)
+	orgID, inboxID := uuid.New(), uuid.New()
+	repo.inboxes[inboxID] = "leads@in.example.net"
+
+	domain, err := svc.CreateDomain(context.Background(), orgID, uuid.New(), types.DomainRequest{
+		Domain: " Mail.Example.COM. ", Kind: types.DomainSending, ReplyToInboxID: &inboxID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com", domain.Domain)
+	assert.Equal(t, types.DomainPending, domain.Status)
+	assert.Contains(t, domain.DKIMPrivateKey, "PRIVATE KEY")
+	require.Len(t, domain.Records, 2)
+	dkim := domain.Records[0]
+	assert.Equal(t, domain.DKIMSelector+"._domainkey.mail.example.com", dkim.Name)
+
+	// Whitespace inserted into long DKIM values by DNS providers is insignificant
+	key := domain.DKIMPublicKey
+	resolver.txt[dkim.Name] = []string{"v=DKIM1; k=rsa; p=" + key[:100] + " " + key[100:]}
+	resolver.txt["mail.example.com"] = []string{"google-site-verification=abc", "v=spf1 include:_spf.google.com " + DefaultSPFInclude + " ~all"}
+
+	verified, err := svc.VerifyDomain(context.Background(), orgID, domain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DomainVerified, verified.Status)
+	require.NotNil(t, verified.VerifiedAt)
+
+	sender, err := svc.Sender(context.Background(), orgID)
+	require.NoError(t, err)
+	assert.Equal(t, "mail.example.com", sender.Domain)
+	require.NotNil(t, sender.ReplyTo)
+	assert.Equal(t, "leads@in.example.net", *sender.ReplyTo)
+}
+
+func TestMissingSPFKeepsDomainPending(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	repo := newFakeEmailDomainRepo()
+	resolver := &fakeResolver{txt: map[string][]string{}}
+	svc := newTestService(repo, resolver, now)
+	orgID := uuid.New()
+
+	domain, err := svc.CreateDomain(context.Background(), orgID, uuid.New(), types.DomainRequest{Domain: "example.com", Kind: types.DomainSending})
+	require.NoError(t, err)
+	resolver.txt[domain.Records[0].Name] = []string{domain.Records[0].Value}
+
+	checked, err := svc.VerifyDomain(context.Background(), orgID, domain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DomainPending, checked.Status)
+	assert.True(t, checked.Records[0].Verified)
+	assert.False(t, checked.Records[1].Verified)
+	assert.Equal(t, "no TXT record found at example.com", checked.Records[1].Error)
+
+	// Two SPF records are a permanent error for receivers
+	resolver.txt["example.com"] = []string{"v=spf1 " + DefaultSPFInclude + " -all", "v=spf1 mx -all"}
+	svc.now = func() time.Time { return now.Add(PendingWindow + time.Hour) }
+	checked, err = svc.VerifyDomain(context.Background(), orgID, domain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DomainFailed, checked.Status)
+	assert.Equal(t, "example.com publishes more than one SPF record", checked.Records[1].Error)
+}
+
+func TestVerifiedDomainFailsWhenRecordRemoved(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	repo := newFakeEmailDomainRepo()
+	resolver := &fakeResolver{txt: map[string][]string{}, cname: map[string]string{}}
+	svc := newTestService(repo, resolver, now)
+	orgID := uuid.New()
+
+	domain, err := svc.CreateDomain(context.Background(), orgID, uuid.New(), types.DomainRequest{Domain: "track.example.com", Kind: types.DomainTracking})
+	require.NoError(t, err)
+	assert.Empty(t, domain.DKIMSelector)
+	resolver.cname["track.example.com"] = DefaultTrackingTarget + "."
+
+	run, err := svc.VerifyDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, types.VerificationRun{Checked: 1, Verified: 1}, *run)
+
+	url, err := svc.TrackingURL(context.Background(), orgID, "/public/v1/track/abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://track.example.com/public/v1/track/abc", url)
+	owner, err := svc.OrganizationForHost(context.Background(), "Track.Example.com:443")
+	require.NoError(t, err)
+	assert.Equal(t, orgID, owner)
+
+	// Verified domains are only rechecked daily
+	svc.now = func() time.Time { return now.Add(2 * time.Hour) }
+	run, err = svc.VerifyDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Checked)
+
+	delete(resolver.cname, "track.example.com")
+	svc.now = func() time.Time { return now.Add(RecheckInterval + time.Hour) }
+	run, err = svc.VerifyDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, types.VerificationRun{Checked: 1, Failed: 1}, *run)
+	assert.Equal(t, "no CNAME record found at track.example.com", repo.domains[domain.ID].Records[0].Error)
+
+	_, err = svc.TrackingURL(context.Background(), orgID, "/x")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestDomainVerifiedByAnotherOrganization(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	repo := newFakeEmailDomainRepo()
+	resolver := &fakeResolver{cname: map[string]string{"track.example.com": DefaultTrackingTarget}}
+	svc := newTestService(repo, resolver, now)
+
+	first, err := svc.CreateDomain(context.Background(), uuid.New(), uuid.New(), types.DomainRequest{Domain: "track.example.com", Kind: types.DomainTracking})
+	require.NoError(t, err)
+	_, err = svc.VerifyDomain(context.Background(), first.OrganizationID, first.ID)
+	require.NoError(t, err)
+
+	second, err := svc.CreateDomain(context.Background(), uuid.New(), uuid.New(), types.DomainRequest{Domain: "track.example.com", Kind: types.DomainTracking})
+	require.NoError(t, err)
+	checked, err := svc.VerifyDomain(context.Background(), second.OrganizationID, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.DomainPending, checked.Status)
+	assert.Equal(t, "domain is verified by another organization", checked.Records[0].Error)
+}
+
+func TestCreateDomainValidation(t *testing.T) {
+	repo := newFakeEmailDomainRepo()
+	svc := newTestService(repo, &fakeResolver{}, time.Now())
+	orgID, inboxID := uuid.New(), uuid.New()
+	repo.inboxes[inboxID] = "leads@in.example.net"
+
+	for _, req := range []types.DomainRequest{
+		{Domain: "localhost", Kind: types.DomainSending},
+		{Domain: "10.0.0.1", Kind: types.DomainSending},
+		{Domain: "-bad.example.com", Kind: types.DomainSending},
+		{Domain: "mail_server.example.com", Kind: types.DomainSending},
+		{Domain: "example.com", Kind: "marketing"},
+		{Domain: "track.example.com", Kind: types.DomainTracking, ReplyToInboxID: &inboxID},
+		{Domain: "example.com", Kind: types.DomainSending, ReplyToInboxID: func() *uuid.UUID { id := uuid.New(); return &id }()},
+	} {
+		_, err := svc.CreateDomain(context.Background(), orgID, uuid.New(), req)
+		assert.ErrorIs(t, err, ErrInvalid, req.Domain)
+	}
+	assert.Empty(t, repo.domains)
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DomainKind is what an organization uses a domain for
+type DomainKind string
+
+const (
+	// DomainSending is a domain emails are sent from, signed with the
+	// domain's DKIM key and authorized by its SPF record
+	DomainSending DomainKind = "sending"
+	// DomainTracking is a host that serves tracking pages and links under
+	// the organization's brand, pointed at the platform by a CNAME record
+	DomainTracking DomainKind = "tracking"
+)
+
+// IsValid reports whether the kind is known
+func (k DomainKind) IsValid() bool {
+	return k == DomainSending || k == DomainTracking
+}
+
+// DomainStatus is how far a domain's DNS records have been verified
+type DomainStatus string
+
+const (
+	// DomainPending domains wait for their records to be published
+	DomainPending  DomainStatus = "pending"
+	DomainVerified DomainStatus = "verified"
+	// DomainFailed domains were not verified in time, or lost a record
+	// after they were verified
+	DomainFailed DomainStatus = "failed"
+)
+
+// RecordPurpose is what a DNS record proves
+type RecordPurpose string
+
+const (
+	RecordDKIM     RecordPurpose = "dkim"
+	RecordSPF      RecordPurpose = "spf"
+	RecordTracking RecordPurpose = "tracking"
+)
+
+// DNSRecord is a record the organization must publish, with the outcome of
+// its last check
+type DNSRecord struct {
+	Purpose RecordPurpose `json:"purpose"`
+	Type    string        `json:"type"`
+	Name    string        `json:"name"`
+	// Value is the expected record; for SPF, the mechanism the domain's
+	// SPF record must contain
+	Value    string `json:"value"`
+	Verified bool   `json:"verified"`
+	// Error tells why the record did not verify
+	Error string `json:"error,omitempty"`
+}
+
+// Domain is a domain an organization sends email from or serves tracking
+// pages on
+type Domain struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	Domain         string       `json:"domain"`
+	Kind           DomainKind   `json:"kind"`
+	Status         DomainStatus `json:"status"`
+	// DKIMSelector and DKIMPublicKey are set on sending domains. The
+	// private key signs outgoing mail and is never returned.
+	DKIMSelector   string `json:"dkim_selector,omitempty"`
+	DKIMPublicKey  string `json:"dkim_public_key,omitempty"`
+	DKIMPrivateKey string `json:"-"`
+	// ReplyToInboxID routes replies to mail sent from the domain to a CRM
+	// inbound mailbox; ReplyTo is that mailbox's address
+	ReplyToInboxID *uuid.UUID  `json:"reply_to_inbox_id,omitempty"`
+	ReplyTo        *string     `json:"reply_to,omitempty"`
+	Records        []DNSRecord `json:"records"`
+	LastCheckedAt  *time.Time  `json:"last_checked_at,omitempty"`
+	VerifiedAt     *time.Time  `json:"verified_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	CreatedBy      *uuid.UUID  `json:"created_by,omitempty"`
+}
+
+// DomainRequest adds a domain
+type DomainRequest struct {
+	Domain         string     `json:"domain"`
+	Kind           DomainKind `json:"kind"`
+	ReplyToInboxID *uuid.UUID `json:"reply_to_inbox_id,omitempty"`
+}
+
+// ReplyToRequest changes where replies to a sending domain's mail go; a
+// nil inbox leaves replies to the sender
+type ReplyToRequest struct {
+	ReplyToInboxID *uuid.UUID `json:"reply_to_inbox_id"`
+}
+
+// Sender is the identity outgoing mail of an organization is sent with
+type Sender struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Domain         string    `json:"domain"`
+	DKIMSelector   string    `json:"dkim_selector"`
+	DKIMPrivateKey string    `json:"-"`
+	ReplyTo        *string   `json:"reply_to,omitempty"`
+}
+
+// VerificationRun summarizes one pass of the verification worker
+type VerificationRun struct {
+	Checked  int `json:"checked"`
+	Verified int `json:"verified"`
+	Failed   int `json:"failed"`
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EntitlementsHandler handles HTTP requests for subscription plans and what
+// they entitle an organization to
+type EntitlementsHandler struct {
+	service *service.EntitlementsService
+}
+
+func NewEntitlementsHandler(service *service.EntitlementsService) *EntitlementsHandler {
+	return &EntitlementsHandler{service: service}
+}
+
+func (h *EntitlementsHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/entitlements", h.GetEntitlements)
+	router.GET("/api/v1/me/quota", h.GetQuota)
+	router.GET("/api/v1/plans", h.ListPlans)
+	router.PUT("/api/v1/admin/organizations/:id/plan", h.SetPlan)
+}
+
+// writeEntitlementsError maps service errors to HTTP statuses
+func writeEntitlementsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrPlanNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrOrganizationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetEntitlements handles GET /api/v1/entitlements
+func (h *EntitlementsHandler) GetEntitlements(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetEntitlements(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeEntitlementsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetQuota handles GET /api/v1/me/quota
+func (h *EntitlementsHandler) GetQuota(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	quota, err := h.service.GetQuota(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeEntitlementsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quota)
+}
+
+// ListPlans handles GET /api/v1/plans
+func (h *EntitlementsHandler) ListPlans(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	plans, err := h.service.ListPlans(r.Context())
+	if err != nil {
+		writeEntitlementsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+// SetPlan handles PUT /api/v1/admin/organizations/:id/plan
+func (h *EntitlementsHandler) SetPlan(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	orgID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SetPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.service.SetPlan(r.Context(), orgID, req)
+	if err != nil {
+		writeEntitlementsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
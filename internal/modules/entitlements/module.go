@@ -0,0 +1,83 @@
+package entitlements
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/service"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EntitlementsModule represents the entitlements module: subscription plans,
+// the limits and features they include, and their enforcement
+type EntitlementsModule struct {
+	entitlementsService *service.EntitlementsService
+	entitlementsHandler *handler.EntitlementsHandler
+	logger              *slog.Logger
+}
+
+// NewEntitlementsModule creates a new entitlements module
+func NewEntitlementsModule() *EntitlementsModule {
+	return &EntitlementsModule{}
+}
+
+// Name returns the module name
+func (m *EntitlementsModule) Name() string {
+	return "entitlements"
+}
+
+// Init initializes the entitlements module
+func (m *EntitlementsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "entitlements")
+	m.logger.Info("Initializing entitlements module")
+
+	// Create repositories
+	entitlementsRepo := repository.NewEntitlementsRepository(deps.DB)
+
+	// Create services
+	m.entitlementsService = service.NewEntitlementsService(entitlementsRepo, m.logger)
+
+	// Create handlers
+	m.entitlementsHandler = handler.NewEntitlementsHandler(m.entitlementsService)
+
+	m.logger.Info("Entitlements module initialized successfully")
+	return nil
+}
+
+// EntitlementsService checks organizations' plans for the modules that
+// enforce them and the rate limiter. It is nil before Init.
+func (m *EntitlementsModule) EntitlementsService() *service.EntitlementsService {
+	return m.entitlementsService
+}
+
+// SetUsageReader sets where quotas read metered usage from. It must be
+// called after Init.
+func (m *EntitlementsModule) SetUsageReader(usage metering.Reader) {
+	if m.entitlementsService != nil && usage != nil {
+		m.entitlementsService.SetUsageReader(usage)
+	}
+}
+
+// RegisterRoutes registers entitlements module routes
+func (m *EntitlementsModule) RegisterRoutes(router interface{}) {
+	if m.entitlementsHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.entitlementsHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the entitlements module
+func (m *EntitlementsModule) RegisterEventHandlers(bus interface{}) {
+	// Plans are checked on demand; no events needed
+}
+
+// Health checks the health of the entitlements module
+func (m *EntitlementsModule) Health() error {
+	return nil
+}
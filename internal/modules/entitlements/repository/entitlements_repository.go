@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/types"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EntitlementsRepo defines the interface for entitlements repository operations
+type EntitlementsRepo interface {
+	ListPlans(ctx context.Context) ([]types.Plan, error)
+	FindPlan(ctx context.Context, code string) (*types.Plan, error)
+	FindPlanForOrganization(ctx context.Context, orgID uuid.UUID) (*types.Plan, error)
+	CountUsage(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit) (int, error)
+	// CountAdded counts the records of a counted limit the organization
+	// added since a time, whether or not they still count
+	CountAdded(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit, since time.Time) (int, error)
+	SetOrganizationPlan(ctx context.Context, orgID uuid.UUID, code string) error
+}
+
+// EntitlementsRepository reads subscription plans and counts what they limit
+type EntitlementsRepository struct {
+	db *sql.DB
+}
+
+// Ensure EntitlementsRepository implements EntitlementsRepo interface
+var _ EntitlementsRepo = &EntitlementsRepository{}
+
+func NewEntitlementsRepository(db *sql.DB) *EntitlementsRepository {
+	return &EntitlementsRepository{db: db}
+}
+
+// usageQueries count an organization's use of each counted limit
+var usageQueries = map[entitlements.Limit]string{
+	entitlements.LimitUsers:       `SELECT COUNT(*) FROM organization_users WHERE organization_id = $1 AND is_active = true`,
+	entitlements.LimitLeads:       `SELECT COUNT(*) FROM leads WHERE organization_id = $1 AND deleted_at IS NULL`,
+	entitlements.LimitAutomations: `SELECT COUNT(*) FROM assignment_rules WHERE organization_id = $1 AND COALESCE(is_active, true)`,
+}
+
+// addedQueries count the records of each counted limit an organization
+// added since a time
+var addedQueries = map[entitlements.Limit]string{
+	entitlements.LimitUsers:       `SELECT COUNT(*) FROM organization_users WHERE organization_id = $1 AND created_at >= $2`,
+	entitlements.LimitLeads:       `SELECT COUNT(*) FROM leads WHERE organization_id = $1 AND created_at >= $2`,
+	entitlements.LimitAutomations: `SELECT COUNT(*) FROM assignment_rules WHERE organization_id = $1 AND created_at >= $2`,
+}
+
+const planColumns = `p.code, p.name, p.rank, p.max_users, p.max_leads, p.max_automations, p.api_rate_per_minute,
+	p.features, p.upgrade_to, p.updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlan(row rowScanner) (*types.Plan, error) {
+	var plan types.Plan
+	err := row.Scan(&plan.Code, &plan.Name, &plan.Rank, &plan.MaxUsers, &plan.MaxLeads, &plan.MaxAutomations,
+		&plan.APIRatePerMinute, pq.Array(&plan.Features), &plan.UpgradeTo, &plan.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Features == nil {
+		plan.Features = []string{}
+	}
+	return &plan, nil
+}
+
+// ListPlans returns the plans from the lowest to the highest
+func (r *EntitlementsRepository) ListPlans(ctx context.Context) ([]types.Plan, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+planColumns+` FROM subscription_plans p ORDER BY p.rank, p.code`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []types.Plan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plan: %w", err)
+		}
+		plans = append(plans, *plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating plans: %w", err)
+	}
+	return plans, nil
+}
+
+func (r *EntitlementsRepository) FindPlan(ctx context.Context, code string) (*types.Plan, error) {
+	plan, err := scanPlan(r.db.QueryRowContext(ctx, `SELECT `+planColumns+` FROM subscription_plans p WHERE p.code = $1`, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find plan: %w", err)
+	}
+	return plan, nil
+}
+
+// FindPlanForOrganization returns the plan named by the organization's tier,
+// or by its production organization's tier for a sandbox, and the default
+// plan when the tier names none
+func (r *EntitlementsRepository) FindPlanForOrganization(ctx context.Context, orgID uuid.UUID) (*types.Plan, error) {
+	plan, err := scanPlan(r.db.QueryRowContext(ctx, `
+		SELECT `+planColumns+`
+		FROM subscription_plans p
+		WHERE p.code = COALESCE((
+			SELECT tier.code
+			FROM organizations o
+			LEFT JOIN organizations parent ON parent.id = o.sandbox_parent_id
+			JOIN subscription_plans tier ON tier.code = COALESCE(parent.subscription_tier, o.subscription_tier)
+			WHERE o.id = $1
+		), $2)`,
+		orgID, types.DefaultPlan,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization plan: %w", err)
+	}
+	return plan, nil
+}
+
+// CountUsage counts an organization's use of a counted limit
+func (r *EntitlementsRepository) CountUsage(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit) (int, error) {
+	query, ok := usageQueries[limit]
+	if !ok {
+		return 0, fmt.Errorf("limit %s is not counted", limit)
+	}
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", limit, err)
+	}
+	return count, nil
+}
+
+// CountAdded counts the records of a counted limit the organization added
+// since a time
+func (r *EntitlementsRepository) CountAdded(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit, since time.Time) (int, error) {
+	query, ok := addedQueries[limit]
+	if !ok {
+		return 0, fmt.Errorf("limit %s is not counted", limit)
+	}
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, orgID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s added: %w", limit, err)
+	}
+	return count, nil
+}
+
+// SetOrganizationPlan moves a production organization to a plan; its sandbox
+// follows
+func (r *EntitlementsRepository) SetOrganizationPlan(ctx context.Context, orgID uuid.UUID, code string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE organizations
+		SET subscription_tier = $2, updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL AND NOT is_sandbox`,
+		orgID, code,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set organization plan: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to set organization plan: %w", err)
+	} else if n == 0 {
+		return types.ErrOrganizationNotFound
+	}
+	return nil
+}
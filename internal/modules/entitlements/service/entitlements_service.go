@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+	"github.com/KevTiv/alieze-erp/pkg/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// planFreshFor is how long an organization's plan is used before it is
+	// read again
+	planFreshFor = time.Minute
+	// QuotaTrendDays is the period whose additions project when a limit
+	// will be reached
+	QuotaTrendDays = 30
+)
+
+// EntitlementsService resolves the subscription plan of each organization,
+// enforces its limits and features for the modules that create what it
+// limits, and reports them to the frontend
+type EntitlementsService struct {
+	repo   repository.EntitlementsRepo
+	plans  *cache.SWR[types.Plan]
+	usage  metering.Reader
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// Ensure EntitlementsService implements the entitlements checker
+var _ entitlements.Checker = &EntitlementsService{}
+
+func NewEntitlementsService(repo repository.EntitlementsRepo, logger *slog.Logger) *EntitlementsService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EntitlementsService{
+		repo:   repo,
+		plans:  cache.NewSWR[types.Plan](cache.Options{FreshFor: planFreshFor, ServeStaleFor: 5 * planFreshFor}, logger),
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// SetUsageReader sets where quotas read the organization's metered usage
+// from. Without one, quotas report no usage.
+func (s *EntitlementsService) SetUsageReader(usage metering.Reader) {
+	s.usage = usage
+}
+
+// planFor returns the organization's plan
+func (s *EntitlementsService) planFor(ctx context.Context, orgID uuid.UUID) (types.Plan, error) {
+	entry, err := s.plans.Get(ctx, orgID.String(), false, func(ctx context.Context) (types.Plan, error) {
+		plan, err := s.repo.FindPlanForOrganization(ctx, orgID)
+		if err != nil {
+			return types.Plan{}, err
+		}
+		return *plan, nil
+	})
+	if err != nil {
+		return types.Plan{}, err
+	}
+	return entry.Value, nil
+}
+
+// CheckLimit returns a *entitlements.LimitError when adding more of limit
+// would take the organization past its plan. When the plan or the usage
+// cannot be read the creation is allowed, so an outage of the plan tables
+// does not stop the organization's work.
+func (s *EntitlementsService) CheckLimit(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit, adding int) error {
+	plan, err := s.planFor(ctx, orgID)
+	if err != nil {
+		s.logger.Warn("Failed to load plan, allowing", "organization_id", orgID, "limit", limit, "error", err)
+		return nil
+	}
+	max := plan.Max(limit)
+	if max == nil {
+		return nil
+	}
+
+	used, err := s.repo.CountUsage(ctx, orgID, limit)
+	if err != nil {
+		s.logger.Warn("Failed to count plan usage, allowing", "organization_id", orgID, "limit", limit, "error", err)
+		return nil
+	}
+	if used+adding > *max {
+		return &entitlements.LimitError{Limit: limit, Plan: plan.Code, Max: *max, Current: used, UpgradeTo: plan.UpgradeCode()}
+	}
+	return nil
+}
+
+// CheckFeature returns a *entitlements.FeatureError when the organization's
+// plan does not include the feature. Like CheckLimit, it allows the feature
+// when the plan cannot be read.
+func (s *EntitlementsService) CheckFeature(ctx context.Context, orgID uuid.UUID, feature string) error {
+	plan, err := s.planFor(ctx, orgID)
+	if err != nil {
+		s.logger.Warn("Failed to load plan, allowing", "organization_id", orgID, "feature", feature, "error", err)
+		return nil
+	}
+	if !plan.Includes(feature) {
+		return &entitlements.FeatureError{Feature: feature, Plan: plan.Code, UpgradeTo: plan.UpgradeCode()}
+	}
+	return nil
+}
+
+// RateLimit returns the organization's per-minute API allowance, 0 when its
+// plan sets none or cannot be read
+func (s *EntitlementsService) RateLimit(ctx context.Context, orgID uuid.UUID) int {
+	plan, err := s.planFor(ctx, orgID)
+	if err != nil {
+		s.logger.Warn("Failed to load plan for rate limit", "organization_id", orgID, "error", err)
+		return 0
+	}
+	if plan.APIRatePerMinute == nil {
+		return 0
+	}
+	return *plan.APIRatePerMinute
+}
+
+// GetEntitlements returns the organization's plan and its use of the plan's
+// limits. Every member can read it, so the frontend can hide what the plan
+// does not include and prompt for upgrades.
+func (s *EntitlementsService) GetEntitlements(ctx context.Context, orgID uuid.UUID) (*types.Entitlements, error) {
+	plan, err := s.planFor(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.Entitlements{
+		OrganizationID:   orgID,
+		Plan:             plan,
+		Limits:           make([]types.LimitUsage, 0, len(types.CountedLimits)),
+		APIRatePerMinute: plan.APIRatePerMinute,
+		Features:         plan.Features,
+		UpgradeTo:        plan.UpgradeTo,
+		UpgradeHint:      entitlements.UpgradeHint(plan.UpgradeCode()),
+	}
+	for _, limit := range types.CountedLimits {
+		used, err := s.repo.CountUsage(ctx, orgID, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Limits = append(result.Limits, LimitUsageOf(plan, limit, used))
+	}
+	return result, nil
+}
+
+// LimitUsageOf describes the use of a plan's limit
+func LimitUsageOf(plan types.Plan, limit entitlements.Limit, used int) types.LimitUsage {
+	usage := types.LimitUsage{Limit: limit, Max: plan.Max(limit), Used: used}
+	if usage.Max != nil {
+		remaining := *usage.Max - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.Remaining = &remaining
+		usage.Reached = remaining == 0
+	}
+	return usage
+}
+
+// GetQuota returns what the organization can still do under its plan: the
+// caller's API allowance in the current window, the plan's limits with when
+// they would be reached at the rate of the last QuotaTrendDays, and the
+// metered usage of the current month projected to its end. Every member
+// can read it, so integrators can throttle themselves.
+func (s *EntitlementsService) GetQuota(ctx context.Context, orgID uuid.UUID) (*types.Quota, error) {
+	plan, err := s.planFor(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	quota := &types.Quota{
+		OrganizationID:   orgID,
+		Plan:             plan.Code,
+		GeneratedAt:      now,
+		APIRatePerMinute: plan.APIRatePerMinute,
+		Limits:           make([]types.LimitQuota, 0, len(types.CountedLimits)),
+		TrendDays:        QuotaTrendDays,
+		PeriodStart:      periodStart,
+		PeriodEnd:        periodEnd,
+		Usage:            []types.UsageQuota{},
+		UpgradeTo:        plan.UpgradeTo,
+		UpgradeHint:      entitlements.UpgradeHint(plan.UpgradeCode()),
+	}
+	if res, ok := ratelimit.ResultFrom(ctx); ok {
+		quota.RateLimit = &types.RateLimitQuota{Limit: res.Limit, Remaining: res.Remaining, ResetAt: res.ResetAt.UTC()}
+	}
+
+	since := now.AddDate(0, 0, -QuotaTrendDays)
+	for _, limit := range types.CountedLimits {
+		used, err := s.repo.CountUsage(ctx, orgID, limit)
+		if err != nil {
+			return nil, err
+		}
+		added, err := s.repo.CountAdded(ctx, orgID, limit, since)
+		if err != nil {
+			return nil, err
+		}
+		quota.Limits = append(quota.Limits, projectLimit(LimitUsageOf(plan, limit, used), added, now))
+	}
+
+	if s.usage != nil {
+		totals, err := s.usage.Totals(ctx, orgID, periodStart, now)
+		if err != nil {
+			return nil, err
+		}
+		// Counters are projected from the part of the month elapsed, at
+		// least a day so the first hours do not project wildly
+		elapsed := math.Max(now.Sub(periodStart).Hours()/24, 1)
+		days := periodEnd.Sub(periodStart).Hours() / 24
+		for _, total := range totals {
+			usage := types.UsageQuota{Metric: total.Metric, Gauge: total.Gauge, Used: total.Total, Projected: total.Total}
+			if !total.Gauge {
+				usage.Projected = int64(math.Round(float64(total.Total) / elapsed * days))
+			}
+			quota.Usage = append(quota.Usage, usage)
+		}
+	}
+	return quota, nil
+}
+
+// projectLimit projects when a limit will be reached if records keep being
+// added at the rate of the trend period
+func projectLimit(usage types.LimitUsage, added int, now time.Time) types.LimitQuota {
+	quota := types.LimitQuota{LimitUsage: usage, AddedPerDay: float64(added) / QuotaTrendDays}
+	switch {
+	case usage.Remaining == nil:
+	case usage.Reached:
+		quota.ProjectedExhaustion = &now
+	case added > 0:
+		days := float64(*usage.Remaining) / quota.AddedPerDay
+		exhaustion := now.Add(time.Duration(days * float64(24*time.Hour))).Truncate(time.Second)
+		quota.ProjectedExhaustion = &exhaustion
+	}
+	return quota
+}
+
+// ListPlans returns the plans, from the lowest to the highest
+func (s *EntitlementsService) ListPlans(ctx context.Context) ([]types.Plan, error) {
+	return s.repo.ListPlans(ctx)
+}
+
+// SetPlan moves an organization to another plan. Only platform
+// administrators can change plans.
+func (s *EntitlementsService) SetPlan(ctx context.Context, orgID uuid.UUID, req types.SetPlanRequest) (*types.Plan, error) {
+	authCtx, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !authCtx.IsSuperAdmin {
+		return nil, types.ErrForbidden
+	}
+
+	plan, err := s.repo.FindPlan(ctx, strings.TrimSpace(req.Plan))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetOrganizationPlan(ctx, orgID, plan.Code); err != nil {
+		return nil, err
+	}
+	// Sandboxes follow their production organization's plan, so drop every
+	// cached plan rather than only this organization's
+	s.plans.Invalidate("")
+
+	s.logger.Info("Organization plan changed", "organization_id", orgID, "plan", plan.Code, "changed_by", authCtx.UserID)
+	return plan, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/entitlements/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+	"github.com/KevTiv/alieze-erp/pkg/ratelimit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEntitlementsRepo struct {
+	plans    map[string]types.Plan
+	orgPlans map[uuid.UUID]string
+	usage    map[entitlements.Limit]int
+	added    map[entitlements.Limit]int
+	countErr error
+}
+
+func intPtr(v int) *int { return &v }
+
+func strPtr(v string) *string { return &v }
+
+func newFakeEntitlementsRepo() *fakeEntitlementsRepo {
+	return &fakeEntitlementsRepo{
+		plans: map[string]types.Plan{
+			"free":       {Code: "free", MaxUsers: intPtr(5), MaxLeads: intPtr(500), MaxAutomations: intPtr(2), APIRatePerMinute: intPtr(120), Features: []string{}, UpgradeTo: strPtr("starter")},
+			"starter":    {Code: "starter", MaxLeads: intPtr(10000), Features: []string{entitlements.FeatureSandbox}, UpgradeTo: strPtr("enterprise")},
+			"enterprise": {Code: "enterprise", Features: []string{entitlements.FeatureSandbox}},
+		},
+		orgPlans: make(map[uuid.UUID]string),
+		usage:    make(map[entitlements.Limit]int),
+		added:    make(map[entitlements.Limit]int),
+	}
+}
+
+func (f *fakeEntitlementsRepo) ListPlans(ctx context.Context) ([]types.Plan, error) {
+	return nil, nil
+}
+
+func (f *fakeEntitlementsRepo) FindPlan(ctx context.Context, code string) (*types.Plan, error) {
+	plan, ok := f.plans[code]
+	if !ok {
+		return nil, types.ErrPlanNotFound
+	}
+	return &plan, nil
+}
+
+func (f *fakeEntitlementsRepo) FindPlanForOrganization(ctx context.Context, orgID uuid.UUID) (*types.Plan, error) {
+	code, ok := f.orgPlans[orgID]
+	if !ok {
+		code = types.DefaultPlan
+	}
+	return f.FindPlan(ctx, code)
+}
+
+func (f *fakeEntitlementsRepo) CountUsage(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit) (int, error) {
+	return f.usage[limit], f.countErr
+}
+
+func (f *fakeEntitlementsRepo) CountAdded(ctx context.Context, orgID uuid.UUID, limit entitlements.Limit, since time.Time) (int, error) {
+	return f.added[limit], f.countErr
+}
+
+func (f *fakeEntitlementsRepo) SetOrganizationPlan(ctx context.Context, orgID uuid.UUID, code string) error {
+	f.orgPlans[orgID] = code
+	return nil
+}
+
+func TestCheckLimit(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	s := NewEntitlementsService(repo, nil)
+	orgID := uuid.New()
+	ctx := context.Background()
+
+	repo.usage[entitlements.LimitLeads] = 499
+	require.NoError(t, s.CheckLimit(ctx, orgID, entitlements.LimitLeads, 1))
+
+	repo.usage[entitlements.LimitLeads] = 500
+	err := s.CheckLimit(ctx, orgID, entitlements.LimitLeads, 1)
+	var limitErr *entitlements.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, entitlements.LimitError{Limit: entitlements.LimitLeads, Plan: "free", Max: 500, Current: 500, UpgradeTo: "starter"}, *limitErr)
+
+	// Unlimited on the plan
+	repo.orgPlans[orgID] = "enterprise"
+	s = NewEntitlementsService(repo, nil)
+	assert.NoError(t, s.CheckLimit(ctx, orgID, entitlements.LimitLeads, 1))
+}
+
+func TestCheckLimitAllowsWhenUsageCannotBeCounted(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	repo.usage[entitlements.LimitAutomations] = 10
+	repo.countErr = errors.New("connection refused")
+	s := NewEntitlementsService(repo, nil)
+
+	assert.NoError(t, s.CheckLimit(context.Background(), uuid.New(), entitlements.LimitAutomations, 1))
+}
+
+func TestCheckFeature(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	s := NewEntitlementsService(repo, nil)
+	freeOrg, starterOrg := uuid.New(), uuid.New()
+	repo.orgPlans[starterOrg] = "starter"
+
+	err := s.CheckFeature(context.Background(), freeOrg, entitlements.FeatureSandbox)
+	var featureErr *entitlements.FeatureError
+	require.True(t, errors.As(err, &featureErr))
+	assert.Equal(t, "starter", featureErr.UpgradeTo)
+	assert.NoError(t, s.CheckFeature(context.Background(), starterOrg, entitlements.FeatureSandbox))
+}
+
+func TestGetEntitlements(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	repo.usage[entitlements.LimitUsers] = 6
+	repo.usage[entitlements.LimitLeads] = 120
+	s := NewEntitlementsService(repo, nil)
+
+	result, err := s.GetEntitlements(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "free", result.Plan.Code)
+	require.Len(t, result.Limits, 3)
+
+	users := result.Limits[0]
+	assert.Equal(t, entitlements.LimitUsers, users.Limit)
+	assert.Equal(t, 0, *users.Remaining)
+	assert.True(t, users.Reached)
+
+	leads := result.Limits[1]
+	assert.Equal(t, 380, *leads.Remaining)
+	assert.False(t, leads.Reached)
+	assert.Equal(t, "Upgrade to the starter plan to continue.", result.UpgradeHint)
+}
+
+func TestSetPlanRequiresPlatformAdministrator(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	s := NewEntitlementsService(repo, nil)
+	orgID := uuid.New()
+
+	ctx := auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: uuid.New(), OrganizationID: orgID})
+	_, err := s.SetPlan(ctx, orgID, types.SetPlanRequest{Plan: "starter"})
+	assert.ErrorIs(t, err, types.ErrForbidden)
+
+	ctx = auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: uuid.New(), OrganizationID: uuid.New(), IsSuperAdmin: true})
+	_, err = s.SetPlan(ctx, orgID, types.SetPlanRequest{Plan: "platinum"})
+	assert.ErrorIs(t, err, types.ErrPlanNotFound)
+
+	// The free plan is cached, then replaced once the plan changes
+	assert.Equal(t, 120, s.RateLimit(ctx, orgID))
+	plan, err := s.SetPlan(ctx, orgID, types.SetPlanRequest{Plan: "starter"})
+	require.NoError(t, err)
+	assert.Equal(t, "starter", plan.Code)
+	assert.Equal(t, 0, s.RateLimit(ctx, orgID))
+}
+
+type fakeUsageReader struct {
+	totals   []metering.Total
+	from, to time.Time
+}
+
+func (f *fakeUsageReader) Totals(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]metering.Total, error) {
+	f.from, f.to = from, to
+	return f.totals, nil
+}
+
+func TestGetQuota(t *testing.T) {
+	repo := newFakeEntitlementsRepo()
+	repo.usage[entitlements.LimitUsers] = 5
+	repo.usage[entitlements.LimitLeads] = 200
+	repo.added[entitlements.LimitLeads] = 300
+	usage := &fakeUsageReader{totals: []metering.Total{
+		{Metric: "api_calls", Total: 1000},
+		{Metric: "stored_records", Gauge: true, Total: 4096},
+	}}
+	s := NewEntitlementsService(repo, nil)
+	s.SetUsageReader(usage)
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	resetAt := now.Add(30 * time.Second)
+	ctx := ratelimit.WithResult(context.Background(), ratelimit.Result{Allowed: true, Limit: 120, Remaining: 80, ResetAt: resetAt})
+	quota, err := s.GetQuota(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "free", quota.Plan)
+	assert.Equal(t, &types.RateLimitQuota{Limit: 120, Remaining: 80, ResetAt: resetAt}, quota.RateLimit)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), usage.from)
+	assert.Equal(t, now, usage.to)
+	require.Len(t, quota.Limits, 3)
+
+	// Users are at the limit already
+	users := quota.Limits[0]
+	assert.True(t, users.Reached)
+	assert.Equal(t, now, *users.ProjectedExhaustion)
+
+	// 300 leads remain and 10 are added a day
+	leads := quota.Limits[1]
+	assert.Equal(t, 10.0, leads.AddedPerDay)
+	assert.Equal(t, now.AddDate(0, 0, 30), *leads.ProjectedExhaustion)
+
+	// No automations were added, so they are never reached
+	assert.Nil(t, quota.Limits[2].ProjectedExhaustion)
+
+	// 9.5 of 30 days have passed; gauges are not projected
+	require.Len(t, quota.Usage, 2)
+	assert.Equal(t, types.UsageQuota{Metric: "api_calls", Used: 1000, Projected: 3158}, quota.Usage[0])
+	assert.Equal(t, types.UsageQuota{Metric: "stored_records", Gauge: true, Used: 4096, Projected: 4096}, quota.Usage[1])
+}
@@ -0,0 +1,150 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlan is the plan of organizations whose tier names no plan
+const DefaultPlan = "free"
+
+var (
+	// ErrPlanNotFound is returned when no plan has the code
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrOrganizationNotFound is returned when the organization does not exist
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrForbidden is returned when the caller is not a platform administrator
+	ErrForbidden = errors.New("plans can only be changed by platform administrators")
+)
+
+// Plan is a subscription plan: the limits and features an organization on it
+// gets. A nil limit is unlimited.
+type Plan struct {
+	Code             string    `json:"code"`
+	Name             string    `json:"name"`
+	Rank             int       `json:"rank"`
+	MaxUsers         *int      `json:"max_users"`
+	MaxLeads         *int      `json:"max_leads"`
+	MaxAutomations   *int      `json:"max_automations"`
+	APIRatePerMinute *int      `json:"api_rate_per_minute"`
+	Features         []string  `json:"features"`
+	UpgradeTo        *string   `json:"upgrade_to,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Max returns the plan's cap on a limit, nil when unlimited
+func (p Plan) Max(limit entitlements.Limit) *int {
+	switch limit {
+	case entitlements.LimitUsers:
+		return p.MaxUsers
+	case entitlements.LimitLeads:
+		return p.MaxLeads
+	case entitlements.LimitAutomations:
+		return p.MaxAutomations
+	case entitlements.LimitAPIRate:
+		return p.APIRatePerMinute
+	}
+	return nil
+}
+
+// Includes reports whether the plan includes a feature
+func (p Plan) Includes(feature string) bool {
+	for _, f := range p.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// UpgradeCode returns the plan to upgrade to, empty on the top plan
+func (p Plan) UpgradeCode() string {
+	if p.UpgradeTo == nil {
+		return ""
+	}
+	return *p.UpgradeTo
+}
+
+// CountedLimits are the limits counted against the organization's records;
+// the API rate is enforced per minute by the rate limiter
+var CountedLimits = []entitlements.Limit{entitlements.LimitUsers, entitlements.LimitLeads, entitlements.LimitAutomations}
+
+// LimitUsage is an organization's use of one of its plan's limits
+type LimitUsage struct {
+	Limit entitlements.Limit `json:"limit"`
+	Max   *int               `json:"max"`
+	Used  int                `json:"used"`
+	// Remaining is nil when the limit is unlimited
+	Remaining *int `json:"remaining"`
+	Reached   bool `json:"reached"`
+}
+
+// Entitlements is what an organization's plan allows and how much of it the
+// organization uses, for the frontend to show limits and upgrade prompts
+type Entitlements struct {
+	OrganizationID   uuid.UUID    `json:"organization_id"`
+	Plan             Plan         `json:"plan"`
+	Limits           []LimitUsage `json:"limits"`
+	APIRatePerMinute *int         `json:"api_rate_per_minute"`
+	Features         []string     `json:"features"`
+	UpgradeTo        *string      `json:"upgrade_to,omitempty"`
+	UpgradeHint      string       `json:"upgrade_hint"`
+}
+
+// SetPlanRequest moves an organization to another plan
+type SetPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// LimitQuota is an organization's use of a limit of its plan, and when it
+// would reach it at the rate the limited records were added lately
+type LimitQuota struct {
+	LimitUsage
+	// AddedPerDay is the daily average added over the trend period
+	AddedPerDay float64 `json:"added_per_day"`
+	// ProjectedExhaustion is nil when the limit is unlimited or nothing is
+	// being added; it is the current time once the limit is reached
+	ProjectedExhaustion *time.Time `json:"projected_exhaustion"`
+}
+
+// RateLimitQuota is the caller's API allowance in the current window, as
+// also reported by the X-RateLimit headers
+type RateLimitQuota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// UsageQuota is an organization's metered usage of a metric in the current
+// billing period, a calendar month in UTC
+type UsageQuota struct {
+	Metric string `json:"metric"`
+	Gauge  bool   `json:"gauge"`
+	Used   int64  `json:"used"`
+	// Projected is the usage expected by the end of the period at the rate
+	// so far; gauges project their current peak
+	Projected int64 `json:"projected"`
+}
+
+// Quota is what an organization can still do under its plan, for
+// integrators to throttle themselves
+type Quota struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Plan           string    `json:"plan"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	// RateLimit is nil when the request was not rate limited
+	RateLimit        *RateLimitQuota `json:"rate_limit"`
+	APIRatePerMinute *int            `json:"api_rate_per_minute"`
+	Limits           []LimitQuota    `json:"limits"`
+	// TrendDays is the period limit projections are based on
+	TrendDays   int          `json:"trend_days"`
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end"`
+	Usage       []UsageQuota `json:"usage"`
+	UpgradeTo   *string      `json:"upgrade_to,omitempty"`
+	UpgradeHint string       `json:"upgrade_hint"`
+}
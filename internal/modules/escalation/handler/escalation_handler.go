@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EscalationHandler handles HTTP requests for escalation policies
+type EscalationHandler struct {
+	service *service.EscalationService
+}
+
+func NewEscalationHandler(service *service.EscalationService) *EscalationHandler {
+	return &EscalationHandler{service: service}
+}
+
+func (h *EscalationHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/escalation-policies", h.ListPolicies)
+	router.GET("/api/v1/escalation-policies/:code", h.GetPolicy)
+	router.PUT("/api/v1/escalation-policies/:code", h.SavePolicy)
+	router.DELETE("/api/v1/escalation-policies/:code", h.DeletePolicy)
+	router.GET("/api/v1/escalation-policies/:code/preview", h.Preview)
+}
+
+// ListPolicies handles GET /api/v1/escalation-policies
+func (h *EscalationHandler) ListPolicies(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	policies, err := h.service.ListPolicies(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeEscalationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policies)
+}
+
+// GetPolicy handles GET /api/v1/escalation-policies/:code
+func (h *EscalationHandler) GetPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	policy, err := h.service.GetPolicy(r.Context(), authCtx.OrganizationID, ps.ByName("code"))
+	if err != nil {
+		writeEscalationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// SavePolicy handles PUT /api/v1/escalation-policies/:code
+func (h *EscalationHandler) SavePolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.EscalationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.service.SavePolicy(r.Context(), authCtx.OrganizationID, ps.ByName("code"), req)
+	if err != nil {
+		writeEscalationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /api/v1/escalation-policies/:code
+func (h *EscalationHandler) DeletePolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePolicy(r.Context(), authCtx.OrganizationID, ps.ByName("code")); err != nil {
+		writeEscalationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Preview handles GET /api/v1/escalation-policies/:code/preview?user_id=
+// Without user_id the preview is for the calling user.
+func (h *EscalationHandler) Preview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	subjectID := authCtx.UserID
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		subjectID = id
+	}
+
+	preview, err := h.service.Preview(r.Context(), authCtx.OrganizationID, subjectID, ps.ByName("code"))
+	if err != nil {
+		writeEscalationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEscalationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
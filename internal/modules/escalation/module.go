@@ -0,0 +1,70 @@
+package escalation
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/hierarchy"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EscalationModule represents the escalation policy and manager hierarchy module
+type EscalationModule struct {
+	escalationHandler *handler.EscalationHandler
+	logger            *slog.Logger
+}
+
+// NewEscalationModule creates a new escalation module
+func NewEscalationModule() *EscalationModule {
+	return &EscalationModule{}
+}
+
+// Name returns the module name
+func (m *EscalationModule) Name() string {
+	return "escalation"
+}
+
+// Init initializes the escalation module
+func (m *EscalationModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "escalation")
+	m.logger.Info("Initializing escalation module")
+
+	// Create repositories
+	escalationRepo := repository.NewEscalationRepository(deps.DB)
+	hierarchyStore := hierarchy.NewStore(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	escalationService := service.NewEscalationService(escalationRepo, hierarchyStore.Resolver(), authAdapter, m.logger)
+
+	// Create handlers
+	m.escalationHandler = handler.NewEscalationHandler(escalationService)
+
+	m.logger.Info("Escalation module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers escalation module routes
+func (m *EscalationModule) RegisterRoutes(router interface{}) {
+	if m.escalationHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.escalationHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the escalation module
+func (m *EscalationModule) RegisterEventHandlers(bus interface{}) {
+	// Hierarchy lookups are cached with a TTL; no events needed
+}
+
+// Health checks the health of the escalation module
+func (m *EscalationModule) Health() error {
+	return nil
+}
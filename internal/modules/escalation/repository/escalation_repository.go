@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when an escalation policy does not exist in the organization
+var ErrNotFound = errors.New("not found")
+
+// EscalationRepo defines the interface for escalation policy repository operations
+type EscalationRepo interface {
+	ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.EscalationPolicy, error)
+	FindPolicy(ctx context.Context, orgID uuid.UUID, code string) (*types.EscalationPolicy, error)
+	SavePolicy(ctx context.Context, policy types.EscalationPolicy) (*types.EscalationPolicy, error)
+	DeletePolicy(ctx context.Context, orgID uuid.UUID, code string) error
+}
+
+// EscalationRepository persists escalation policies
+type EscalationRepository struct {
+	db *sql.DB
+}
+
+// Ensure EscalationRepository implements EscalationRepo interface
+var _ EscalationRepo = &EscalationRepository{}
+
+func NewEscalationRepository(db *sql.DB) *EscalationRepository {
+	return &EscalationRepository{db: db}
+}
+
+const policyColumns = `id, organization_id, code, name, levels, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPolicy(row rowScanner) (*types.EscalationPolicy, error) {
+	var policy types.EscalationPolicy
+	var levelsJSON []byte
+	err := row.Scan(
+		&policy.ID, &policy.OrganizationID, &policy.Code, &policy.Name, &levelsJSON,
+		&policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(levelsJSON, &policy.Levels); err != nil {
+		return nil, fmt.Errorf("invalid escalation levels: %w", err)
+	}
+	return &policy, nil
+}
+
+func (r *EscalationRepository) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.EscalationPolicy, error) {
+	query := `SELECT ` + policyColumns + ` FROM escalation_policies WHERE organization_id = $1 ORDER BY code`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []types.EscalationPolicy
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan escalation policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list escalation policies: %w", err)
+	}
+	return policies, nil
+}
+
+func (r *EscalationRepository) FindPolicy(ctx context.Context, orgID uuid.UUID, code string) (*types.EscalationPolicy, error) {
+	query := `SELECT ` + policyColumns + ` FROM escalation_policies WHERE organization_id = $1 AND code = $2`
+
+	policy, err := scanPolicy(r.db.QueryRowContext(ctx, query, orgID, code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("escalation policy %q %w", code, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find escalation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SavePolicy creates the policy or replaces the name and levels of the existing one with the same code
+func (r *EscalationRepository) SavePolicy(ctx context.Context, policy types.EscalationPolicy) (*types.EscalationPolicy, error) {
+	levelsJSON, err := json.Marshal(policy.Levels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode escalation levels: %w", err)
+	}
+
+	query := `
+		INSERT INTO escalation_policies (id, organization_id, code, name, levels)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id, code) DO UPDATE SET
+			name = EXCLUDED.name,
+			levels = EXCLUDED.levels,
+			updated_at = now()
+		RETURNING ` + policyColumns
+
+	saved, err := scanPolicy(r.db.QueryRowContext(ctx, query,
+		policy.ID, policy.OrganizationID, policy.Code, policy.Name, levelsJSON,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save escalation policy: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *EscalationRepository) DeletePolicy(ctx context.Context, orgID uuid.UUID, code string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM escalation_policies WHERE organization_id = $1 AND code = $2`, orgID, code)
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation policy: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation policy: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("escalation policy %q %w", code, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/types"
+	"github.com/KevTiv/alieze-erp/pkg/hierarchy"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalid wraps validation failures of escalation policy requests
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the escalation service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// HierarchyResolver resolves the management chain and escalation recipients of a user
+type HierarchyResolver interface {
+	Chain(ctx context.Context, orgID, userID uuid.UUID) ([]hierarchy.Person, error)
+	Escalate(ctx context.Context, orgID, subjectID uuid.UUID, policy hierarchy.Policy) ([]hierarchy.Escalation, error)
+}
+
+// EscalationService manages escalation policies and previews their resolution
+type EscalationService struct {
+	repo        repository.EscalationRepo
+	resolver    HierarchyResolver
+	authService AuthService
+	logger      *slog.Logger
+}
+
+func NewEscalationService(repo repository.EscalationRepo, resolver HierarchyResolver, authService AuthService, logger *slog.Logger) *EscalationService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EscalationService{
+		repo:        repo,
+		resolver:    resolver,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+// ListPolicies returns the policies configured for the organization
+func (s *EscalationService) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.EscalationPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "escalation_policies:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListPolicies(ctx, orgID)
+}
+
+// GetPolicy returns a policy by code. The default code falls back to the
+// built-in policy when the organization has not configured its own.
+func (s *EscalationService) GetPolicy(ctx context.Context, orgID uuid.UUID, code string) (*types.EscalationPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "escalation_policies:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.findPolicy(ctx, orgID, code)
+}
+
+func (s *EscalationService) findPolicy(ctx context.Context, orgID uuid.UUID, code string) (*types.EscalationPolicy, error) {
+	policy, err := s.repo.FindPolicy(ctx, orgID, code)
+	if errors.Is(err, repository.ErrNotFound) && code == hierarchy.DefaultPolicyCode {
+		builtin := hierarchy.DefaultPolicy()
+		return &types.EscalationPolicy{
+			OrganizationID: orgID,
+			Code:           builtin.Code,
+			Name:           builtin.Name,
+			Levels:         builtin.Levels,
+			Builtin:        true,
+		}, nil
+	}
+	return policy, err
+}
+
+// SavePolicy creates or replaces the policy with the given code
+func (s *EscalationService) SavePolicy(ctx context.Context, orgID uuid.UUID, code string, req types.EscalationPolicyRequest) (*types.EscalationPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "escalation_policies:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	policy := types.EscalationPolicy{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Code:           strings.TrimSpace(code),
+		Name:           strings.TrimSpace(req.Name),
+		Levels:         req.Levels,
+	}
+	if policy.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if err := policy.Policy().Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	saved, err := s.repo.SavePolicy(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Saved escalation policy", "code", saved.Code, "levels", len(saved.Levels))
+	return saved, nil
+}
+
+// DeletePolicy removes a policy. Deleting the default policy restores the built-in one.
+func (s *EscalationService) DeletePolicy(ctx context.Context, orgID uuid.UUID, code string) error {
+	if err := s.authService.CheckPermission(ctx, "escalation_policies:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeletePolicy(ctx, orgID, code)
+}
+
+// Preview resolves who the subject user's work would be escalated to under the policy
+func (s *EscalationService) Preview(ctx context.Context, orgID, subjectID uuid.UUID, code string) (*types.EscalationPreview, error) {
+	if err := s.authService.CheckPermission(ctx, "escalation_policies:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	policy, err := s.findPolicy(ctx, orgID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := s.resolver.Chain(ctx, orgID, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve management chain: %w", err)
+	}
+
+	escalations, err := s.resolver.Escalate(ctx, orgID, subjectID, policy.Policy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve escalation: %w", err)
+	}
+
+	if chain == nil {
+		chain = []hierarchy.Person{}
+	}
+	return &types.EscalationPreview{
+		SubjectUserID:   subjectID,
+		Policy:          *policy,
+		ManagementChain: chain,
+		Escalations:     escalations,
+	}, nil
+}
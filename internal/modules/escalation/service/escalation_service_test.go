@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/escalation/types"
+	"github.com/KevTiv/alieze-erp/pkg/hierarchy"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEscalationRepo struct {
+	policies map[string]types.EscalationPolicy
+}
+
+func (f *fakeEscalationRepo) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.EscalationPolicy, error) {
+	var out []types.EscalationPolicy
+	for _, p := range f.policies {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeEscalationRepo) FindPolicy(ctx context.Context, orgID uuid.UUID, code string) (*types.EscalationPolicy, error) {
+	p, ok := f.policies[code]
+	if !ok || p.OrganizationID != orgID {
+		return nil, fmt.Errorf("escalation policy %q %w", code, repository.ErrNotFound)
+	}
+	return &p, nil
+}
+
+func (f *fakeEscalationRepo) SavePolicy(ctx context.Context, policy types.EscalationPolicy) (*types.EscalationPolicy, error) {
+	f.policies[policy.Code] = policy
+	return &policy, nil
+}
+
+func (f *fakeEscalationRepo) DeletePolicy(ctx context.Context, orgID uuid.UUID, code string) error {
+	delete(f.policies, code)
+	return nil
+}
+
+// fakeResolver escalates every level to the same manager
+type fakeResolver struct {
+	manager  uuid.UUID
+	policies []hierarchy.Policy
+}
+
+func (f *fakeResolver) Chain(ctx context.Context, orgID, userID uuid.UUID) ([]hierarchy.Person, error) {
+	return nil, nil
+}
+
+func (f *fakeResolver) Escalate(ctx context.Context, orgID, subjectID uuid.UUID, policy hierarchy.Policy) ([]hierarchy.Escalation, error) {
+	f.policies = append(f.policies, policy)
+	out := make([]hierarchy.Escalation, len(policy.Levels))
+	for i := range policy.Levels {
+		out[i] = hierarchy.Escalation{Level: i + 1, Recipient: &hierarchy.Person{UserID: &f.manager}}
+	}
+	return out, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService() (*EscalationService, *fakeEscalationRepo, *fakeResolver) {
+	repo := &fakeEscalationRepo{policies: make(map[string]types.EscalationPolicy)}
+	resolver := &fakeResolver{manager: uuid.New()}
+	return NewEscalationService(repo, resolver, allowAll{}, nil), repo, resolver
+}
+
+func TestSavePolicyValidation(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestService()
+	orgID := uuid.New()
+	levels := []hierarchy.Level{{Targets: []hierarchy.Target{{Kind: hierarchy.TargetManager}}}}
+
+	_, err := svc.SavePolicy(ctx, orgID, "sla", types.EscalationPolicyRequest{Levels: levels})
+	assert.True(t, errors.Is(err, ErrInvalid), "name is required")
+
+	_, err = svc.SavePolicy(ctx, orgID, "SLA Breach", types.EscalationPolicyRequest{Name: "SLA", Levels: levels})
+	assert.True(t, errors.Is(err, ErrInvalid), "code must be lowercase")
+
+	_, err = svc.SavePolicy(ctx, orgID, "sla", types.EscalationPolicyRequest{
+		Name:   "SLA",
+		Levels: []hierarchy.Level{{Targets: []hierarchy.Target{{Kind: hierarchy.TargetOrgRole}}}},
+	})
+	assert.True(t, errors.Is(err, ErrInvalid), "org_role needs a role")
+
+	saved, err := svc.SavePolicy(ctx, orgID, "sla", types.EscalationPolicyRequest{Name: " SLA ", Levels: levels})
+	require.NoError(t, err)
+	assert.Equal(t, "SLA", saved.Name)
+}
+
+func TestPreviewFallsBackToBuiltinDefault(t *testing.T) {
+	ctx := context.Background()
+	svc, _, resolver := newTestService()
+	orgID, subjectID := uuid.New(), uuid.New()
+
+	preview, err := svc.Preview(ctx, orgID, subjectID, hierarchy.DefaultPolicyCode)
+	require.NoError(t, err)
+	assert.True(t, preview.Policy.Builtin)
+	assert.Equal(t, subjectID, preview.SubjectUserID)
+	assert.NotNil(t, preview.ManagementChain)
+	assert.Len(t, preview.Escalations, len(hierarchy.DefaultPolicy().Levels))
+	assert.Equal(t, hierarchy.DefaultPolicy(), resolver.policies[0])
+
+	_, err = svc.Preview(ctx, orgID, subjectID, "approvals")
+	assert.True(t, errors.Is(err, repository.ErrNotFound), "only the default policy has a built-in fallback")
+}
+
+func TestPreviewUsesConfiguredDefault(t *testing.T) {
+	ctx := context.Background()
+	svc, _, resolver := newTestService()
+	orgID := uuid.New()
+
+	_, err := svc.SavePolicy(ctx, orgID, hierarchy.DefaultPolicyCode, types.EscalationPolicyRequest{
+		Name:   "Team first",
+		Levels: []hierarchy.Level{{Targets: []hierarchy.Target{{Kind: hierarchy.TargetTeamLeader}}}},
+	})
+	require.NoError(t, err)
+
+	preview, err := svc.Preview(ctx, orgID, uuid.New(), hierarchy.DefaultPolicyCode)
+	require.NoError(t, err)
+	assert.False(t, preview.Policy.Builtin)
+	require.Len(t, preview.Escalations, 1)
+	assert.Equal(t, &resolver.manager, preview.Escalations[0].Recipient.UserID)
+	assert.Equal(t, hierarchy.TargetTeamLeader, resolver.policies[0].Levels[0].Targets[0].Kind)
+}
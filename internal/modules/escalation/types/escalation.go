@@ -0,0 +1,43 @@
+package types
+
+import (
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/hierarchy"
+
+	"github.com/google/uuid"
+)
+
+// EscalationPolicy is an organization's escalation matrix, identified by a
+// code that features such as SLAs and approvals refer to
+type EscalationPolicy struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID uuid.UUID         `json:"organization_id"`
+	Code           string            `json:"code"`
+	Name           string            `json:"name"`
+	Levels         []hierarchy.Level `json:"levels"`
+	// Builtin is set when the organization has not configured the default
+	// policy and the built-in one is returned instead
+	Builtin   bool      `json:"builtin,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Policy returns the policy in the form the hierarchy resolver expects
+func (p EscalationPolicy) Policy() hierarchy.Policy {
+	return hierarchy.Policy{Code: p.Code, Name: p.Name, Levels: p.Levels}
+}
+
+// EscalationPolicyRequest creates or replaces the policy with the code in the URL
+type EscalationPolicyRequest struct {
+	Name   string            `json:"name"`
+	Levels []hierarchy.Level `json:"levels"`
+}
+
+// EscalationPreview shows who would be notified if the subject user's work escalated
+type EscalationPreview struct {
+	SubjectUserID   uuid.UUID              `json:"subject_user_id"`
+	Policy          EscalationPolicy       `json:"policy"`
+	ManagementChain []hierarchy.Person     `json:"management_chain"`
+	Escalations     []hierarchy.Escalation `json:"escalations"`
+}
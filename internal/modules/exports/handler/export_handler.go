@@ -0,0 +1,393 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ExportHandler handles export destinations, their schedules and the
+// transfer log
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+func NewExportHandler(service *service.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+func (h *ExportHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/export-sources", h.ListSources)
+
+	router.GET("/api/v1/export-destinations", h.ListDestinations)
+	router.POST("/api/v1/export-destinations", h.CreateDestination)
+	router.GET("/api/v1/export-destinations/:id", h.GetDestination)
+	router.PUT("/api/v1/export-destinations/:id", h.UpdateDestination)
+	router.DELETE("/api/v1/export-destinations/:id", h.DeleteDestination)
+	router.POST("/api/v1/export-destinations/:id/test", h.TestDestination)
+
+	router.GET("/api/v1/export-schedules", h.ListSchedules)
+	router.POST("/api/v1/export-schedules", h.CreateSchedule)
+	router.GET("/api/v1/export-schedules/:id", h.GetSchedule)
+	router.PUT("/api/v1/export-schedules/:id", h.UpdateSchedule)
+	router.DELETE("/api/v1/export-schedules/:id", h.DeleteSchedule)
+	router.POST("/api/v1/export-schedules/:id/run", h.RunSchedule)
+
+	router.GET("/api/v1/export-transfers", h.ListTransfers)
+}
+
+// ListSources handles GET /api/v1/export-sources
+func (h *ExportHandler) ListSources(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	sources, err := h.service.ListSources(r.Context())
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sources)
+}
+
+// ListDestinations handles GET /api/v1/export-destinations
+func (h *ExportHandler) ListDestinations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	destinations, err := h.service.ListDestinations(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, destinations)
+}
+
+// CreateDestination handles POST /api/v1/export-destinations
+func (h *ExportHandler) CreateDestination(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	destination, err := h.service.CreateDestination(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, destination)
+}
+
+// GetDestination handles GET /api/v1/export-destinations/:id
+func (h *ExportHandler) GetDestination(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid destination ID", http.StatusBadRequest)
+		return
+	}
+
+	destination, err := h.service.GetDestination(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, destination)
+}
+
+// UpdateDestination handles PUT /api/v1/export-destinations/:id. Omitting
+// credentials keeps the stored ones.
+func (h *ExportHandler) UpdateDestination(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid destination ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	destination, err := h.service.UpdateDestination(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, destination)
+}
+
+// DeleteDestination handles DELETE /api/v1/export-destinations/:id
+func (h *ExportHandler) DeleteDestination(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid destination ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDestination(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestDestination handles POST /api/v1/export-destinations/:id/test. It
+// connects with the stored credentials and reports whether files can be
+// delivered; a failed connection is reported in the body, not as an error
+// status.
+func (h *ExportHandler) TestDestination(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid destination ID", http.StatusBadRequest)
+		return
+	}
+
+	check, err := h.service.CheckDestination(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, check)
+}
+
+// ListSchedules handles GET /api/v1/export-schedules
+func (h *ExportHandler) ListSchedules(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+// CreateSchedule handles POST /api/v1/export-schedules
+func (h *ExportHandler) CreateSchedule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, schedule)
+}
+
+// GetSchedule handles GET /api/v1/export-schedules/:id
+func (h *ExportHandler) GetSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.GetSchedule(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// UpdateSchedule handles PUT /api/v1/export-schedules/:id
+func (h *ExportHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.service.UpdateSchedule(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// DeleteSchedule handles DELETE /api/v1/export-schedules/:id
+func (h *ExportHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSchedule(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSchedule handles POST /api/v1/export-schedules/:id/run. The export
+// runs before the response, which is its transfer log entry.
+func (h *ExportHandler) RunSchedule(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.RunNow(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+// ListTransfers handles GET /api/v1/export-transfers, optionally filtered
+// by schedule_id and status
+func (h *ExportHandler) ListTransfers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.TransferFilter{OrganizationID: authCtx.OrganizationID}
+	query := r.URL.Query()
+	if v := query.Get("schedule_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+			return
+		}
+		filter.ScheduleID = &id
+	}
+	if v := query.Get("status"); v != "" {
+		status := types.TransferStatus(v)
+		if status != types.TransferSucceeded && status != types.TransferFailed {
+			http.Error(w, "status must be succeeded or failed", http.StatusBadRequest)
+			return
+		}
+		filter.Status = &status
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	transfers, err := h.service.ListTransfers(r.Context(), filter)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeExportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrCredentialsUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,368 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ImportHandler handles import mappings, the watchers importing files with
+// them and the import log
+type ImportHandler struct {
+	service *service.ImportService
+}
+
+func NewImportHandler(service *service.ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+func (h *ImportHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/import-entities", h.ListEntities)
+
+	router.GET("/api/v1/import-mappings", h.ListMappings)
+	router.POST("/api/v1/import-mappings", h.CreateMapping)
+	router.GET("/api/v1/import-mappings/:id", h.GetMapping)
+	router.PUT("/api/v1/import-mappings/:id", h.UpdateMapping)
+	router.DELETE("/api/v1/import-mappings/:id", h.DeleteMapping)
+
+	router.GET("/api/v1/import-watchers", h.ListWatchers)
+	router.POST("/api/v1/import-watchers", h.CreateWatcher)
+	router.GET("/api/v1/import-watchers/:id", h.GetWatcher)
+	router.PUT("/api/v1/import-watchers/:id", h.UpdateWatcher)
+	router.DELETE("/api/v1/import-watchers/:id", h.DeleteWatcher)
+	router.POST("/api/v1/import-watchers/:id/poll", h.PollWatcher)
+
+	router.GET("/api/v1/import-files", h.ListImportFiles)
+	router.GET("/api/v1/import-files/:id", h.GetImportFile)
+}
+
+// ListEntities handles GET /api/v1/import-entities
+func (h *ImportHandler) ListEntities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	entities, err := h.service.ListEntities(r.Context())
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entities)
+}
+
+// ListMappings handles GET /api/v1/import-mappings
+func (h *ImportHandler) ListMappings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	mappings, err := h.service.ListMappings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mappings)
+}
+
+// CreateMapping handles POST /api/v1/import-mappings
+func (h *ImportHandler) CreateMapping(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.MappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := h.service.CreateMapping(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapping)
+}
+
+// GetMapping handles GET /api/v1/import-mappings/:id
+func (h *ImportHandler) GetMapping(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := h.service.GetMapping(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapping)
+}
+
+// UpdateMapping handles PUT /api/v1/import-mappings/:id
+func (h *ImportHandler) UpdateMapping(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.MappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := h.service.UpdateMapping(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapping)
+}
+
+// DeleteMapping handles DELETE /api/v1/import-mappings/:id
+func (h *ImportHandler) DeleteMapping(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteMapping(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWatchers handles GET /api/v1/import-watchers
+func (h *ImportHandler) ListWatchers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	watchers, err := h.service.ListWatchers(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, watchers)
+}
+
+// CreateWatcher handles POST /api/v1/import-watchers
+func (h *ImportHandler) CreateWatcher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.WatcherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	watcher, err := h.service.CreateWatcher(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, watcher)
+}
+
+// GetWatcher handles GET /api/v1/import-watchers/:id
+func (h *ImportHandler) GetWatcher(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid watcher ID", http.StatusBadRequest)
+		return
+	}
+
+	watcher, err := h.service.GetWatcher(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, watcher)
+}
+
+// UpdateWatcher handles PUT /api/v1/import-watchers/:id
+func (h *ImportHandler) UpdateWatcher(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid watcher ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.WatcherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	watcher, err := h.service.UpdateWatcher(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, watcher)
+}
+
+// DeleteWatcher handles DELETE /api/v1/import-watchers/:id
+func (h *ImportHandler) DeleteWatcher(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid watcher ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteWatcher(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PollWatcher handles POST /api/v1/import-watchers/:id/poll. The files are
+// imported before the response; a poll that cannot reach them is reported
+// in the body, not as an error status.
+func (h *ImportHandler) PollWatcher(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid watcher ID", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := h.service.PollNow(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poll)
+}
+
+// ListImportFiles handles GET /api/v1/import-files, optionally filtered by
+// watcher_id and status
+func (h *ImportHandler) ListImportFiles(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.ImportFileFilter{OrganizationID: authCtx.OrganizationID}
+	query := r.URL.Query()
+	if v := query.Get("watcher_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid watcher ID", http.StatusBadRequest)
+			return
+		}
+		filter.WatcherID = &id
+	}
+	if v := query.Get("status"); v != "" {
+		status := types.ImportFileStatus(v)
+		if !status.IsValid() {
+			http.Error(w, "status must be succeeded, partial or failed", http.StatusBadRequest)
+			return
+		}
+		filter.Status = &status
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	files, err := h.service.ListImportFiles(r.Context(), filter)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
+
+// GetImportFile handles GET /api/v1/import-files/:id, with the file's row
+// errors
+func (h *ImportHandler) GetImportFile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid import file ID", http.StatusBadRequest)
+		return
+	}
+
+	file, err := h.service.GetImportFile(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, file)
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeRunExports = "exports.run"
+
+// RunJobHandler handles queued passes over the due export schedules
+type RunJobHandler struct {
+	exportService *service.ExportService
+	logger        *slog.Logger
+}
+
+func NewRunJobHandler(exportService *service.ExportService, logger *slog.Logger) *RunJobHandler {
+	return &RunJobHandler{
+		exportService: exportService,
+		logger:        logger,
+	}
+}
+
+// Handle runs the export schedules whose time has come
+func (h *RunJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.exportService.RunDue(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run export schedules: %w", err)
+	}
+	if run.Ran > 0 {
+		h.logger.Info("Export schedules run", "ran", run.Ran, "failed", run.Failed)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *RunJobHandler) JobType() string {
+	return JobTypeRunExports
+}
+
+// JobHandler is a pass the scheduler runs on its interval
+type JobHandler interface {
+	Handle(ctx context.Context, job *queue.Job) error
+	JobType() string
+}
+
+// Scheduler runs a pass over due export schedules or import watchers on a
+// fixed interval. Only the leader runs it, so each schedule runs once however
+// many servers there are.
+type Scheduler struct {
+	handler     JobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler JobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start runs the handler every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	jobType := s.handler.JobType()
+	err := s.coordinator.Every(ctx, jobType, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: jobType, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled pass failed", "job_type", jobType, "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule passes", "job_type", jobType, "error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+)
+
+const JobTypeWatchImports = "imports.watch"
+
+// WatchJobHandler handles queued passes over the due import watchers
+type WatchJobHandler struct {
+	importService *service.ImportService
+	logger        *slog.Logger
+}
+
+func NewWatchJobHandler(importService *service.ImportService, logger *slog.Logger) *WatchJobHandler {
+	return &WatchJobHandler{
+		importService: importService,
+		logger:        logger,
+	}
+}
+
+// Handle polls the import watchers whose time has come
+func (h *WatchJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	run, err := h.importService.RunDueWatchers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to poll import watchers: %w", err)
+	}
+	if run.Polled > 0 {
+		h.logger.Info("Import watchers polled", "polled", run.Polled, "failed", run.Failed, "files", run.Files)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *WatchJobHandler) JobType() string {
+	return JobTypeWatchImports
+}
@@ -0,0 +1,110 @@
+package exports
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ExportsModule represents the scheduled exports and import watchers module
+type ExportsModule struct {
+	exportService  *service.ExportService
+	importService  *service.ImportService
+	exportHandler  *handler.ExportHandler
+	importHandler  *handler.ImportHandler
+	scheduler      *jobs.Scheduler
+	watchScheduler *jobs.Scheduler
+	logger         *slog.Logger
+}
+
+// NewExportsModule creates a new exports module
+func NewExportsModule() *ExportsModule {
+	return &ExportsModule{}
+}
+
+// Name returns the module name
+func (m *ExportsModule) Name() string {
+	return "exports"
+}
+
+// Init initializes the exports module and starts running due schedules and
+// polling due import watchers
+func (m *ExportsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "exports")
+	m.logger.Info("Initializing exports module")
+
+	// Create repositories
+	exportRepo := repository.NewExportRepository(deps.DB)
+	importRepo := repository.NewImportRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.exportService = service.NewExportService(exportRepo, authAdapter, deps.EventBus, m.logger)
+	m.importService = service.NewImportService(importRepo, m.exportService, authAdapter, deps.EventBus, m.logger)
+
+	// Create scheduled runs
+	runJobHandler := jobs.NewRunJobHandler(m.exportService, m.logger)
+	m.scheduler = jobs.NewScheduler(runJobHandler, service.RunInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+	watchJobHandler := jobs.NewWatchJobHandler(m.importService, m.logger)
+	m.watchScheduler = jobs.NewScheduler(watchJobHandler, service.WatchInterval, deps.Scheduler, m.logger)
+	m.watchScheduler.Start(ctx)
+
+	// Create handlers
+	m.exportHandler = handler.NewExportHandler(m.exportService)
+	m.importHandler = handler.NewImportHandler(m.importService)
+
+	m.logger.Info("Exports module initialized successfully")
+	return nil
+}
+
+// RegisterSource makes a report of another module available to schedules
+func (m *ExportsModule) RegisterSource(code, name string, source service.Source) {
+	if m.exportService != nil {
+		m.exportService.RegisterSource(code, name, source)
+	}
+}
+
+// SetCredentialKey sets the secret destination credentials are encrypted with
+func (m *ExportsModule) SetCredentialKey(secret string) {
+	if m.exportService != nil {
+		m.exportService.SetCredentialKey(secret)
+	}
+}
+
+// SetMailer sets the mailer export failure alerts and import error reports
+// are sent with
+func (m *ExportsModule) SetMailer(mailer service.Mailer) {
+	if m.exportService != nil {
+		m.exportService.SetMailer(mailer)
+	}
+}
+
+// RegisterRoutes registers exports module routes
+func (m *ExportsModule) RegisterRoutes(router interface{}) {
+	if m.exportHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.exportHandler.RegisterRoutes(r)
+			m.importHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the exports module
+func (m *ExportsModule) RegisterEventHandlers(bus interface{}) {
+	// Exports run and watchers poll on a schedule or on request; failures
+	// and imported files are published
+}
+
+// Health checks the health of the exports module
+func (m *ExportsModule) Health() error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EntityExtract is a built-in export of one kind of record. Its query takes
+// the organization ($1) and, for incremental runs, the time rows must have
+// changed after ($2, NULL for all rows), and selects the columns as text.
+type EntityExtract struct {
+	Code    string
+	Name    string
+	Columns []string
+	query   string
+}
+
+// utc formats a timestamp column as RFC 3339 in UTC
+func utc(column string) string {
+	return `COALESCE(to_char(` + column + ` AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '')`
+}
+
+// EntityExtracts are the entity extracts every organization can schedule
+var EntityExtracts = []EntityExtract{
+	{
+		Code: "contacts",
+		Name: "Contacts",
+		Columns: []string{"id", "name", "email", "phone", "mobile", "is_company", "is_customer", "is_vendor",
+			"street", "street2", "city", "zip", "country", "tax_id", "reference", "created_at", "updated_at"},
+		query: `SELECT c.id::text, c.name, COALESCE(c.email, ''), COALESCE(c.phone, ''), COALESCE(c.mobile, ''),
+				COALESCE(c.is_company, false)::text, COALESCE(c.is_customer, false)::text, COALESCE(c.is_vendor, false)::text,
+				COALESCE(c.street, ''), COALESCE(c.street2, ''), COALESCE(c.city, ''), COALESCE(c.zip, ''),
+				COALESCE(co.code, ''), COALESCE(c.tax_id, ''), COALESCE(c.reference, ''),
+				` + utc("c.created_at") + `, ` + utc("c.updated_at") + `
+			FROM contacts c
+			LEFT JOIN countries co ON co.id = c.country_id
+			WHERE c.organization_id = $1 AND c.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR c.updated_at > $2)
+			ORDER BY c.created_at, c.id`,
+	},
+	{
+		Code: "products",
+		Name: "Products",
+		Columns: []string{"id", "default_code", "barcode", "name", "product_type", "category", "list_price",
+			"standard_price", "currency", "active", "created_at", "updated_at"},
+		query: `SELECT p.id::text, COALESCE(p.default_code, ''), COALESCE(p.barcode, ''), p.name, COALESCE(p.product_type, ''),
+				COALESCE(pc.complete_name, pc.name, ''), COALESCE(p.list_price, 0)::text, COALESCE(p.standard_price, 0)::text,
+				COALESCE(cur.code, ''), COALESCE(p.active, true)::text,
+				` + utc("p.created_at") + `, ` + utc("p.updated_at") + `
+			FROM products p
+			LEFT JOIN product_categories pc ON pc.id = p.category_id
+			LEFT JOIN currencies cur ON cur.id = p.currency_id
+			WHERE p.organization_id = $1 AND p.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR p.updated_at > $2)
+			ORDER BY p.created_at, p.id`,
+	},
+	{
+		Code: "sales_orders",
+		Name: "Sales orders",
+		Columns: []string{"id", "number", "date_order", "customer_id", "customer", "state", "invoice_status",
+			"delivery_status", "amount_untaxed", "amount_tax", "amount_total", "currency", "client_order_ref",
+			"created_at", "updated_at"},
+		query: `SELECT so.id::text, so.name, ` + utc("so.date_order") + `, so.partner_id::text, c.name,
+				COALESCE(so.state, ''), COALESCE(so.invoice_status, ''), COALESCE(so.delivery_status, ''),
+				COALESCE(so.amount_untaxed, 0)::text, COALESCE(so.amount_tax, 0)::text, COALESCE(so.amount_total, 0)::text,
+				COALESCE(cur.code, ''), COALESCE(so.client_order_ref, ''),
+				` + utc("so.created_at") + `, ` + utc("so.updated_at") + `
+			FROM sales_orders so
+			JOIN contacts c ON c.id = so.partner_id
+			LEFT JOIN currencies cur ON cur.id = so.currency_id
+			WHERE so.organization_id = $1 AND so.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR so.updated_at > $2)
+			ORDER BY so.created_at, so.id`,
+	},
+	{
+		Code: "invoices",
+		Name: "Invoices",
+		Columns: []string{"id", "number", "move_type", "invoice_date", "due_date", "partner_id", "partner", "state",
+			"payment_state", "amount_untaxed", "amount_tax", "amount_total", "amount_residual", "currency",
+			"created_at", "updated_at"},
+		query: `SELECT i.id::text, COALESCE(i.name, ''), i.move_type, COALESCE(i.invoice_date::text, ''),
+				COALESCE(i.invoice_date_due::text, ''), i.partner_id::text, c.name, COALESCE(i.state, ''),
+				COALESCE(i.payment_state, ''), COALESCE(i.amount_untaxed, 0)::text, COALESCE(i.amount_tax, 0)::text,
+				COALESCE(i.amount_total, 0)::text, COALESCE(i.amount_residual, 0)::text, COALESCE(cur.code, ''),
+				` + utc("i.created_at") + `, ` + utc("i.updated_at") + `
+			FROM invoices i
+			JOIN contacts c ON c.id = i.partner_id
+			LEFT JOIN currencies cur ON cur.id = i.currency_id
+			WHERE i.organization_id = $1 AND i.deleted_at IS NULL AND i.move_type <> 'entry'
+				AND ($2::timestamptz IS NULL OR i.updated_at > $2)
+			ORDER BY i.created_at, i.id`,
+	},
+	{
+		Code: "stock_levels",
+		Name: "Stock levels",
+		Columns: []string{"product_id", "default_code", "product", "location_id", "location", "quantity",
+			"reserved_quantity", "updated_at"},
+		query: `SELECT q.product_id::text, COALESCE(p.default_code, ''), p.name, q.location_id::text,
+				COALESCE(l.complete_name, l.name), SUM(COALESCE(q.quantity, 0))::text,
+				SUM(COALESCE(q.reserved_quantity, 0))::text, ` + utc("MAX(q.updated_at)") + `
+			FROM stock_quants q
+			JOIN products p ON p.id = q.product_id
+			JOIN stock_locations l ON l.id = q.location_id
+			WHERE q.organization_id = $1 AND l.usage = 'internal'
+			GROUP BY q.product_id, p.default_code, p.name, q.location_id, l.complete_name, l.name
+			HAVING $2::timestamptz IS NULL OR MAX(q.updated_at) > $2
+			ORDER BY p.name, q.product_id, l.complete_name, q.location_id`,
+	},
+}
+
+// ExtractEntity streams the rows of the extract with code entity to emit,
+// which must not keep the row
+func (r *ExportRepository) ExtractEntity(ctx context.Context, entity string, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error {
+	var extract *EntityExtract
+	for i := range EntityExtracts {
+		if EntityExtracts[i].Code == entity {
+			extract = &EntityExtracts[i]
+		}
+	}
+	if extract == nil {
+		return fmt.Errorf("entity extract %s %w", entity, ErrNotFound)
+	}
+
+	rows, err := r.db.QueryContext(ctx, extract.query, orgID, since)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", entity, err)
+	}
+	defer rows.Close()
+
+	values := make([]sql.NullString, len(extract.Columns))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	row := make([]string, len(values))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", entity, err)
+		}
+		for i, value := range values {
+			row[i] = value.String
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
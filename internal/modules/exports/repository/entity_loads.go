@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EntityLoad is a built-in import of one kind of record. Field names are
+// the columns of its table, except country fields, which are stored as
+// country_id.
+type EntityLoad struct {
+	Code   string
+	Name   string
+	Fields []types.ImportField
+	table  string
+}
+
+// Field returns the load's field called name
+func (l EntityLoad) Field(name string) (types.ImportField, bool) {
+	for _, field := range l.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return types.ImportField{}, false
+}
+
+// EntityLoads are the entities every organization can import into
+var EntityLoads = []EntityLoad{
+	{
+		Code:  "contacts",
+		Name:  "Contacts",
+		table: "contacts",
+		Fields: []types.ImportField{
+			{Name: "name", Kind: types.ImportFieldText, Required: true},
+			{Name: "email", Kind: types.ImportFieldText, Matchable: true},
+			{Name: "phone", Kind: types.ImportFieldText},
+			{Name: "mobile", Kind: types.ImportFieldText},
+			{Name: "is_company", Kind: types.ImportFieldBool},
+			{Name: "is_customer", Kind: types.ImportFieldBool},
+			{Name: "is_vendor", Kind: types.ImportFieldBool},
+			{Name: "street", Kind: types.ImportFieldText},
+			{Name: "street2", Kind: types.ImportFieldText},
+			{Name: "city", Kind: types.ImportFieldText},
+			{Name: "zip", Kind: types.ImportFieldText},
+			{Name: "country", Kind: types.ImportFieldCountry},
+			{Name: "tax_id", Kind: types.ImportFieldText, Matchable: true},
+			{Name: "reference", Kind: types.ImportFieldText, Matchable: true},
+		},
+	},
+	{
+		Code:  "products",
+		Name:  "Products",
+		table: "products",
+		Fields: []types.ImportField{
+			{Name: "default_code", Kind: types.ImportFieldText, Matchable: true},
+			{Name: "barcode", Kind: types.ImportFieldText, Matchable: true},
+			{Name: "name", Kind: types.ImportFieldText, Required: true},
+			{Name: "list_price", Kind: types.ImportFieldNumber},
+			{Name: "standard_price", Kind: types.ImportFieldNumber},
+			{Name: "active", Kind: types.ImportFieldBool},
+		},
+	},
+}
+
+// FindEntityLoad returns the load with code entity
+func FindEntityLoad(entity string) (*EntityLoad, bool) {
+	for i := range EntityLoads {
+		if EntityLoads[i].Code == entity {
+			return &EntityLoads[i], true
+		}
+	}
+	return nil, false
+}
+
+// FieldError is a value of a row that cannot be loaded. Field is empty when
+// the row as a whole was refused.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// LoadEntity creates or updates a record of entity from values, which hold
+// the row's non-empty fields, already checked against their kinds. The
+// record matching values[matchField] is updated, if there is one. Values
+// the record cannot take are reported as a *FieldError.
+func (r *ImportRepository) LoadEntity(ctx context.Context, entity string, orgID uuid.UUID, matchField string, values map[string]string) (bool, error) {
+	load, ok := FindEntityLoad(entity)
+	if !ok {
+		return false, fmt.Errorf("entity load %s %w", entity, ErrNotFound)
+	}
+
+	if len(values) == 0 {
+		return false, &FieldError{Message: "row has no values"}
+	}
+	fields := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := load.Field(name); !ok {
+			return false, fmt.Errorf("entity load %s has no field %s", entity, name)
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+
+	var columns []string
+	args := []interface{}{orgID}
+	for _, name := range fields {
+		field, _ := load.Field(name)
+		value := values[name]
+		column := name
+		switch field.Kind {
+		case types.ImportFieldBool:
+			args = append(args, value)
+			columns = append(columns, fmt.Sprintf("%s = $%d::boolean", column, len(args)))
+			continue
+		case types.ImportFieldNumber:
+			args = append(args, value)
+			columns = append(columns, fmt.Sprintf("%s = $%d::numeric", column, len(args)))
+			continue
+		case types.ImportFieldCountry:
+			countryID, err := r.findCountry(ctx, value)
+			if err != nil {
+				return false, err
+			}
+			if countryID == nil {
+				return false, &FieldError{Field: name, Message: fmt.Sprintf("unknown country %q", value)}
+			}
+			column = "country_id"
+			args = append(args, *countryID)
+		default:
+			args = append(args, value)
+		}
+		columns = append(columns, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	var id *uuid.UUID
+	if match, ok := values[matchField]; ok {
+		found, err := r.findMatch(ctx, load, orgID, matchField, match)
+		if err != nil {
+			return false, err
+		}
+		id = found
+	}
+
+	if id != nil {
+		args = append(args, *id)
+		query := fmt.Sprintf(`UPDATE %s SET %s, updated_at = now() WHERE organization_id = $1 AND id = $%d`,
+			load.table, strings.Join(columns, ", "), len(args))
+		if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+			return false, loadError(entity, err)
+		}
+		return false, nil
+	}
+
+	for _, field := range load.Fields {
+		if _, ok := values[field.Name]; field.Required && !ok {
+			return false, &FieldError{Field: field.Name, Message: "is required for new records"}
+		}
+	}
+	names := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		names[i], placeholders[i], _ = strings.Cut(column, " = ")
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (organization_id, %s, created_at, updated_at) VALUES ($1, %s, now(), now())`,
+		load.table, strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return false, loadError(entity, err)
+	}
+	return true, nil
+}
+
+// findMatch returns the record whose match field equals value, ignoring
+// case, or nil when none does
+func (r *ImportRepository) findMatch(ctx context.Context, load *EntityLoad, orgID uuid.UUID, matchField, value string) (*uuid.UUID, error) {
+	query := fmt.Sprintf(`SELECT id FROM %s
+		WHERE organization_id = $1 AND deleted_at IS NULL AND lower(%s) = lower($2)
+		LIMIT 2`, load.table, matchField)
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match %s: %w", load.Code, err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to match %s: %w", load.Code, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to match %s: %w", load.Code, err)
+	}
+	switch len(ids) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &ids[0], nil
+	default:
+		return nil, &FieldError{Field: matchField, Message: fmt.Sprintf("%q matches more than one record", value)}
+	}
+}
+
+// findCountry returns the country with the ISO code or name, or nil
+func (r *ImportRepository) findCountry(ctx context.Context, value string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM countries
+		WHERE upper(code) = upper($1) OR lower(name) = lower($1)
+		ORDER BY upper(code) = upper($1) DESC
+		LIMIT 1`, value).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find country: %w", err)
+	}
+	return &id, nil
+}
+
+// loadError reports the data and constraint errors of a row as a
+// *FieldError; other errors fail the whole file
+func loadError(entity string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+		return &FieldError{Field: pqErr.Column, Message: pqErr.Message}
+	}
+	return fmt.Errorf("failed to load %s: %w", entity, err)
+}
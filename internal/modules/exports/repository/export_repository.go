@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a destination or schedule does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrInUse is returned when deleting a destination schedules still export
+	// to or watchers still poll, or a mapping watchers still import with
+	ErrInUse = errors.New("still in use")
+)
+
+// ExportRepo defines the interface for export repository operations
+type ExportRepo interface {
+	ListDestinations(ctx context.Context, orgID uuid.UUID) ([]types.Destination, error)
+	FindDestination(ctx context.Context, orgID, id uuid.UUID) (*types.Destination, error)
+	CreateDestination(ctx context.Context, destination types.Destination) (*types.Destination, error)
+	UpdateDestination(ctx context.Context, destination types.Destination) (*types.Destination, error)
+	DeleteDestination(ctx context.Context, orgID, id uuid.UUID) error
+
+	ListSchedules(ctx context.Context, orgID uuid.UUID) ([]types.Schedule, error)
+	FindSchedule(ctx context.Context, orgID, id uuid.UUID) (*types.Schedule, error)
+	CreateSchedule(ctx context.Context, schedule types.Schedule) (*types.Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule types.Schedule) (*types.Schedule, error)
+	DeleteSchedule(ctx context.Context, orgID, id uuid.UUID) error
+	// ListDueSchedules returns up to limit active schedules whose next run
+	// is at or before now, of every organization
+	ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]types.Schedule, error)
+	// SetNextRun moves a schedule's next run, before it runs so that a
+	// crash does not run it again
+	SetNextRun(ctx context.Context, orgID, id uuid.UUID, next time.Time) error
+	// RecordRun saves the outcome of a run: the schedule's last run, status,
+	// last success and consecutive failures
+	RecordRun(ctx context.Context, schedule types.Schedule) error
+
+	CreateTransfer(ctx context.Context, transfer types.Transfer) (*types.Transfer, error)
+	ListTransfers(ctx context.Context, filter types.TransferFilter) ([]types.Transfer, error)
+
+	// ExtractEntity emits the rows of a built-in entity extract
+	ExtractEntity(ctx context.Context, entity string, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error
+}
+
+// ExportRepository stores export destinations, schedules and the transfer
+// log, and reads the entity extracts
+type ExportRepository struct {
+	db *sql.DB
+}
+
+// Ensure ExportRepository implements ExportRepo interface
+var _ ExportRepo = &ExportRepository{}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const destinationColumns = `id, organization_id, name, kind, COALESCE(host, ''), COALESCE(port, 0),
+	COALESCE(username, ''), COALESCE(host_key, ''), COALESCE(bucket, ''), COALESCE(region, ''),
+	COALESCE(endpoint, ''), path, sealed_credentials, active, created_by, created_at, updated_at`
+
+func scanDestination(row rowScanner) (*types.Destination, error) {
+	var d types.Destination
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.Name, &d.Kind, &d.Host, &d.Port,
+		&d.Username, &d.HostKey, &d.Bucket, &d.Region,
+		&d.Endpoint, &d.Path, &d.SealedCredentials, &d.Active, &d.CreatedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.HasCredentials = len(d.SealedCredentials) > 0
+	return &d, nil
+}
+
+// ListDestinations returns the organization's destinations by name
+func (r *ExportRepository) ListDestinations(ctx context.Context, orgID uuid.UUID) ([]types.Destination, error) {
+	query := `SELECT ` + destinationColumns + ` FROM export_destinations
+		WHERE organization_id = $1
+		ORDER BY name, id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export destinations: %w", err)
+	}
+	defer rows.Close()
+
+	destinations := []types.Destination{}
+	for rows.Next() {
+		destination, err := scanDestination(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export destination: %w", err)
+		}
+		destinations = append(destinations, *destination)
+	}
+	return destinations, rows.Err()
+}
+
+// FindDestination returns one of the organization's destinations
+func (r *ExportRepository) FindDestination(ctx context.Context, orgID, id uuid.UUID) (*types.Destination, error) {
+	query := `SELECT ` + destinationColumns + ` FROM export_destinations WHERE organization_id = $1 AND id = $2`
+
+	destination, err := scanDestination(r.db.QueryRowContext(ctx, query, orgID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("export destination %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find export destination: %w", err)
+	}
+	return destination, nil
+}
+
+// CreateDestination inserts a destination
+func (r *ExportRepository) CreateDestination(ctx context.Context, d types.Destination) (*types.Destination, error) {
+	query := `
+		INSERT INTO export_destinations (
+			id, organization_id, name, kind, host, port, username, host_key, bucket, region,
+			endpoint, path, sealed_credentials, active, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, 0), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''),
+			NULLIF($11, ''), $12, $13, $14, $15, now(), now())
+		RETURNING ` + destinationColumns
+
+	destination, err := scanDestination(r.db.QueryRowContext(ctx, query,
+		d.ID, d.OrganizationID, d.Name, d.Kind, d.Host, d.Port, d.Username, d.HostKey, d.Bucket, d.Region,
+		d.Endpoint, d.Path, d.SealedCredentials, d.Active, d.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export destination: %w", err)
+	}
+	return destination, nil
+}
+
+// UpdateDestination replaces a destination's settings and credentials
+func (r *ExportRepository) UpdateDestination(ctx context.Context, d types.Destination) (*types.Destination, error) {
+	query := `
+		UPDATE export_destinations SET
+			name = $3, kind = $4, host = NULLIF($5, ''), port = NULLIF($6, 0), username = NULLIF($7, ''),
+			host_key = NULLIF($8, ''), bucket = NULLIF($9, ''), region = NULLIF($10, ''), endpoint = NULLIF($11, ''),
+			path = $12, sealed_credentials = $13, active = $14, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING ` + destinationColumns
+
+	destination, err := scanDestination(r.db.QueryRowContext(ctx, query,
+		d.OrganizationID, d.ID, d.Name, d.Kind, d.Host, d.Port, d.Username,
+		d.HostKey, d.Bucket, d.Region, d.Endpoint,
+		d.Path, d.SealedCredentials, d.Active))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("export destination %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update export destination: %w", err)
+	}
+	return destination, nil
+}
+
+// DeleteDestination deletes a destination no schedule or watcher uses
+func (r *ExportRepository) DeleteDestination(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM export_destinations WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("export destination is %w by a schedule or import watcher", ErrInUse)
+		}
+		return fmt.Errorf("failed to delete export destination: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("export destination %w", ErrNotFound)
+	}
+	return nil
+}
+
+const scheduleColumns = `id, organization_id, name, destination_id, source, filename, incremental,
+	frequency, to_char(time_of_day, 'HH24:MI'), weekday, day_of_month, timezone, alert_emails, active,
+	next_run_at, last_run_at, last_status, last_success_at, consecutive_failures, created_by, created_at, updated_at`
+
+func scanSchedule(row rowScanner) (*types.Schedule, error) {
+	var s types.Schedule
+	var weekday, dayOfMonth sql.NullInt64
+	var lastStatus sql.NullString
+	var alertEmails pq.StringArray
+	err := row.Scan(&s.ID, &s.OrganizationID, &s.Name, &s.DestinationID, &s.Source, &s.Filename, &s.Incremental,
+		&s.Frequency, &s.TimeOfDay, &weekday, &dayOfMonth, &s.Timezone, &alertEmails, &s.Active,
+		&s.NextRunAt, &s.LastRunAt, &lastStatus, &s.LastSuccessAt, &s.ConsecutiveFailures, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if weekday.Valid {
+		v := int(weekday.Int64)
+		s.Weekday = &v
+	}
+	if dayOfMonth.Valid {
+		v := int(dayOfMonth.Int64)
+		s.DayOfMonth = &v
+	}
+	if lastStatus.Valid {
+		status := types.TransferStatus(lastStatus.String)
+		s.LastStatus = &status
+	}
+	s.AlertEmails = []string(alertEmails)
+	if s.AlertEmails == nil {
+		s.AlertEmails = []string{}
+	}
+	return &s, nil
+}
+
+func (r *ExportRepository) querySchedules(ctx context.Context, query string, args ...interface{}) ([]types.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []types.Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// ListSchedules returns the organization's schedules by name
+func (r *ExportRepository) ListSchedules(ctx context.Context, orgID uuid.UUID) ([]types.Schedule, error) {
+	return r.querySchedules(ctx, `SELECT `+scheduleColumns+` FROM export_schedules
+		WHERE organization_id = $1
+		ORDER BY name, id`, orgID)
+}
+
+// FindSchedule returns one of the organization's schedules
+func (r *ExportRepository) FindSchedule(ctx context.Context, orgID, id uuid.UUID) (*types.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM export_schedules WHERE organization_id = $1 AND id = $2`
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query, orgID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("export schedule %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find export schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// CreateSchedule inserts a schedule
+func (r *ExportRepository) CreateSchedule(ctx context.Context, s types.Schedule) (*types.Schedule, error) {
+	query := `
+		INSERT INTO export_schedules (
+			id, organization_id, name, destination_id, source, filename, incremental, frequency, time_of_day,
+			weekday, day_of_month, timezone, alert_emails, active, next_run_at, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::time, $10, $11, $12, $13, $14, $15, $16, now(), now())
+		RETURNING ` + scheduleColumns
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query,
+		s.ID, s.OrganizationID, s.Name, s.DestinationID, s.Source, s.Filename, s.Incremental, s.Frequency, s.TimeOfDay,
+		s.Weekday, s.DayOfMonth, s.Timezone, pq.Array(s.AlertEmails), s.Active, s.NextRunAt, s.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces a schedule's settings and next run
+func (r *ExportRepository) UpdateSchedule(ctx context.Context, s types.Schedule) (*types.Schedule, error) {
+	query := `
+		UPDATE export_schedules SET
+			name = $3, destination_id = $4, source = $5, filename = $6, incremental = $7, frequency = $8,
+			time_of_day = $9::time, weekday = $10, day_of_month = $11, timezone = $12, alert_emails = $13,
+			active = $14, next_run_at = $15, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING ` + scheduleColumns
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query,
+		s.OrganizationID, s.ID, s.Name, s.DestinationID, s.Source, s.Filename, s.Incremental, s.Frequency,
+		s.TimeOfDay, s.Weekday, s.DayOfMonth, s.Timezone, pq.Array(s.AlertEmails),
+		s.Active, s.NextRunAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("export schedule %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update export schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule deletes a schedule; its transfer log is kept
+func (r *ExportRepository) DeleteSchedule(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM export_schedules WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete export schedule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("export schedule %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListDueSchedules returns the schedules due to run, longest overdue first
+func (r *ExportRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]types.Schedule, error) {
+	return r.querySchedules(ctx, `SELECT `+scheduleColumns+` FROM export_schedules
+		WHERE active AND next_run_at <= $1
+		ORDER BY next_run_at, id
+		LIMIT $2`, now, limit)
+}
+
+// SetNextRun moves a schedule's next run
+func (r *ExportRepository) SetNextRun(ctx context.Context, orgID, id uuid.UUID, next time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE export_schedules SET next_run_at = $3 WHERE organization_id = $1 AND id = $2`, orgID, id, next)
+	if err != nil {
+		return fmt.Errorf("failed to schedule export: %w", err)
+	}
+	return nil
+}
+
+// RecordRun saves the outcome of a schedule's run
+func (r *ExportRepository) RecordRun(ctx context.Context, s types.Schedule) error {
+	query := `
+		UPDATE export_schedules SET
+			last_run_at = $3, last_status = $4, last_success_at = $5, consecutive_failures = $6
+		WHERE organization_id = $1 AND id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, s.OrganizationID, s.ID, s.LastRunAt, s.LastStatus, s.LastSuccessAt, s.ConsecutiveFailures)
+	if err != nil {
+		return fmt.Errorf("failed to record export run: %w", err)
+	}
+	return nil
+}
+
+const transferColumns = `id, organization_id, schedule_id, destination_id, source, trigger, status, remote_path,
+	rows, size, COALESCE(sha256, ''), since, COALESCE(error, ''), started_at, finished_at, triggered_by`
+
+func scanTransfer(row rowScanner) (*types.Transfer, error) {
+	var t types.Transfer
+	err := row.Scan(&t.ID, &t.OrganizationID, &t.ScheduleID, &t.DestinationID, &t.Source, &t.Trigger, &t.Status, &t.RemotePath,
+		&t.Rows, &t.Size, &t.SHA256, &t.Since, &t.Error, &t.StartedAt, &t.FinishedAt, &t.TriggeredBy)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateTransfer appends a run to the transfer log
+func (r *ExportRepository) CreateTransfer(ctx context.Context, t types.Transfer) (*types.Transfer, error) {
+	query := `
+		INSERT INTO export_transfers (
+			id, organization_id, schedule_id, destination_id, source, trigger, status, remote_path,
+			rows, size, sha256, since, error, started_at, finished_at, triggered_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, NULLIF($13, ''), $14, $15, $16)
+		RETURNING ` + transferColumns
+
+	transfer, err := scanTransfer(r.db.QueryRowContext(ctx, query,
+		t.ID, t.OrganizationID, t.ScheduleID, t.DestinationID, t.Source, t.Trigger, t.Status, t.RemotePath,
+		t.Rows, t.Size, t.SHA256, t.Since, t.Error, t.StartedAt, t.FinishedAt, t.TriggeredBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to log export transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// ListTransfers returns transfer log entries, latest first
+func (r *ExportRepository) ListTransfers(ctx context.Context, filter types.TransferFilter) ([]types.Transfer, error) {
+	query := `SELECT ` + transferColumns + ` FROM export_transfers WHERE organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+	if filter.ScheduleID != nil {
+		args = append(args, *filter.ScheduleID)
+		query += fmt.Sprintf(" AND schedule_id = $%d", len(args))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY started_at DESC, id LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []types.Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export transfer: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	return transfers, rows.Err()
+}
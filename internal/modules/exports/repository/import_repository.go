@@ -0,0 +1,455 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ImportRepo defines the interface for import repository operations
+type ImportRepo interface {
+	ListMappings(ctx context.Context, orgID uuid.UUID) ([]types.Mapping, error)
+	FindMapping(ctx context.Context, orgID, id uuid.UUID) (*types.Mapping, error)
+	CreateMapping(ctx context.Context, mapping types.Mapping) (*types.Mapping, error)
+	UpdateMapping(ctx context.Context, mapping types.Mapping) (*types.Mapping, error)
+	DeleteMapping(ctx context.Context, orgID, id uuid.UUID) error
+
+	ListWatchers(ctx context.Context, orgID uuid.UUID) ([]types.Watcher, error)
+	FindWatcher(ctx context.Context, orgID, id uuid.UUID) (*types.Watcher, error)
+	CreateWatcher(ctx context.Context, watcher types.Watcher) (*types.Watcher, error)
+	UpdateWatcher(ctx context.Context, watcher types.Watcher) (*types.Watcher, error)
+	DeleteWatcher(ctx context.Context, orgID, id uuid.UUID) error
+	// ListDueWatchers returns up to limit active watchers whose next poll
+	// is at or before now, of every organization
+	ListDueWatchers(ctx context.Context, now time.Time, limit int) ([]types.Watcher, error)
+	// SetNextPoll moves a watcher's next poll, before it polls so that a
+	// crash does not poll it again
+	SetNextPoll(ctx context.Context, orgID, id uuid.UUID, next time.Time) error
+	// RecordPoll saves the outcome of a poll: the watcher's last poll,
+	// status, error and consecutive failures
+	RecordPoll(ctx context.Context, watcher types.Watcher) error
+
+	// FindImportedFile returns the watcher's import of the file with the
+	// SHA-256 hash, if it read the file
+	FindImportedFile(ctx context.Context, watcherID uuid.UUID, sha256 string) (*types.ImportFile, error)
+	// CreateImportFile logs an imported file with its row errors
+	CreateImportFile(ctx context.Context, file types.ImportFile) (*types.ImportFile, error)
+	// FindImportFile returns a logged file with its row errors
+	FindImportFile(ctx context.Context, orgID, id uuid.UUID) (*types.ImportFile, error)
+	ListImportFiles(ctx context.Context, filter types.ImportFileFilter) ([]types.ImportFile, error)
+
+	// LoadEntity creates or updates a record of a built-in entity load and
+	// reports whether it was created
+	LoadEntity(ctx context.Context, entity string, orgID uuid.UUID, matchField string, values map[string]string) (bool, error)
+}
+
+// ImportRepository stores import mappings, watchers and the import log,
+// and loads the imported rows
+type ImportRepository struct {
+	db *sql.DB
+}
+
+// Ensure ImportRepository implements ImportRepo interface
+var _ ImportRepo = &ImportRepository{}
+
+func NewImportRepository(db *sql.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+const mappingColumns = `id, organization_id, name, entity, columns, COALESCE(match_field, ''), created_by, created_at, updated_at`
+
+func scanMapping(row rowScanner) (*types.Mapping, error) {
+	var m types.Mapping
+	var columns []byte
+	err := row.Scan(&m.ID, &m.OrganizationID, &m.Name, &m.Entity, &columns, &m.MatchField, &m.CreatedBy, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(columns, &m.Columns); err != nil {
+		return nil, fmt.Errorf("invalid mapping columns: %w", err)
+	}
+	if m.Columns == nil {
+		m.Columns = map[string]string{}
+	}
+	return &m, nil
+}
+
+// ListMappings returns the organization's mappings by name
+func (r *ImportRepository) ListMappings(ctx context.Context, orgID uuid.UUID) ([]types.Mapping, error) {
+	query := `SELECT ` + mappingColumns + ` FROM import_mappings
+		WHERE organization_id = $1
+		ORDER BY name, id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []types.Mapping{}
+	for rows.Next() {
+		mapping, err := scanMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import mapping: %w", err)
+		}
+		mappings = append(mappings, *mapping)
+	}
+	return mappings, rows.Err()
+}
+
+// FindMapping returns one of the organization's mappings
+func (r *ImportRepository) FindMapping(ctx context.Context, orgID, id uuid.UUID) (*types.Mapping, error) {
+	query := `SELECT ` + mappingColumns + ` FROM import_mappings WHERE organization_id = $1 AND id = $2`
+
+	mapping, err := scanMapping(r.db.QueryRowContext(ctx, query, orgID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import mapping %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// CreateMapping inserts a mapping
+func (r *ImportRepository) CreateMapping(ctx context.Context, m types.Mapping) (*types.Mapping, error) {
+	columns, err := json.Marshal(m.Columns)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO import_mappings (id, organization_id, name, entity, columns, match_field, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, now(), now())
+		RETURNING ` + mappingColumns
+
+	mapping, err := scanMapping(r.db.QueryRowContext(ctx, query,
+		m.ID, m.OrganizationID, m.Name, m.Entity, columns, m.MatchField, m.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// UpdateMapping replaces a mapping's settings
+func (r *ImportRepository) UpdateMapping(ctx context.Context, m types.Mapping) (*types.Mapping, error) {
+	columns, err := json.Marshal(m.Columns)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE import_mappings SET
+			name = $3, entity = $4, columns = $5, match_field = NULLIF($6, ''), updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING ` + mappingColumns
+
+	mapping, err := scanMapping(r.db.QueryRowContext(ctx, query,
+		m.OrganizationID, m.ID, m.Name, m.Entity, columns, m.MatchField))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import mapping %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update import mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// DeleteMapping deletes a mapping no watcher imports with
+func (r *ImportRepository) DeleteMapping(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM import_mappings WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("import mapping is %w by a watcher", ErrInUse)
+		}
+		return fmt.Errorf("failed to delete import mapping: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("import mapping %w", ErrNotFound)
+	}
+	return nil
+}
+
+const watcherColumns = `id, organization_id, name, destination_id, mapping_id, pattern, archive_path, error_emails,
+	interval_minutes, active, next_poll_at, last_poll_at, last_status, COALESCE(last_error, ''), consecutive_failures,
+	created_by, created_at, updated_at`
+
+func scanWatcher(row rowScanner) (*types.Watcher, error) {
+	var w types.Watcher
+	var lastStatus sql.NullString
+	var errorEmails pq.StringArray
+	err := row.Scan(&w.ID, &w.OrganizationID, &w.Name, &w.DestinationID, &w.MappingID, &w.Pattern, &w.ArchivePath, &errorEmails,
+		&w.IntervalMinutes, &w.Active, &w.NextPollAt, &w.LastPollAt, &lastStatus, &w.LastError, &w.ConsecutiveFailures,
+		&w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastStatus.Valid {
+		status := types.TransferStatus(lastStatus.String)
+		w.LastStatus = &status
+	}
+	w.ErrorEmails = []string(errorEmails)
+	if w.ErrorEmails == nil {
+		w.ErrorEmails = []string{}
+	}
+	return &w, nil
+}
+
+func (r *ImportRepository) queryWatchers(ctx context.Context, query string, args ...interface{}) ([]types.Watcher, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import watchers: %w", err)
+	}
+	defer rows.Close()
+
+	watchers := []types.Watcher{}
+	for rows.Next() {
+		watcher, err := scanWatcher(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import watcher: %w", err)
+		}
+		watchers = append(watchers, *watcher)
+	}
+	return watchers, rows.Err()
+}
+
+// ListWatchers returns the organization's watchers by name
+func (r *ImportRepository) ListWatchers(ctx context.Context, orgID uuid.UUID) ([]types.Watcher, error) {
+	return r.queryWatchers(ctx, `SELECT `+watcherColumns+` FROM import_watchers
+		WHERE organization_id = $1
+		ORDER BY name, id`, orgID)
+}
+
+// FindWatcher returns one of the organization's watchers
+func (r *ImportRepository) FindWatcher(ctx context.Context, orgID, id uuid.UUID) (*types.Watcher, error) {
+	query := `SELECT ` + watcherColumns + ` FROM import_watchers WHERE organization_id = $1 AND id = $2`
+
+	watcher, err := scanWatcher(r.db.QueryRowContext(ctx, query, orgID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import watcher %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import watcher: %w", err)
+	}
+	return watcher, nil
+}
+
+// CreateWatcher inserts a watcher
+func (r *ImportRepository) CreateWatcher(ctx context.Context, w types.Watcher) (*types.Watcher, error) {
+	query := `
+		INSERT INTO import_watchers (
+			id, organization_id, name, destination_id, mapping_id, pattern, archive_path, error_emails,
+			interval_minutes, active, next_poll_at, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, now(), now())
+		RETURNING ` + watcherColumns
+
+	watcher, err := scanWatcher(r.db.QueryRowContext(ctx, query,
+		w.ID, w.OrganizationID, w.Name, w.DestinationID, w.MappingID, w.Pattern, w.ArchivePath, pq.Array(w.ErrorEmails),
+		w.IntervalMinutes, w.Active, w.NextPollAt, w.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import watcher: %w", err)
+	}
+	return watcher, nil
+}
+
+// UpdateWatcher replaces a watcher's settings and next poll
+func (r *ImportRepository) UpdateWatcher(ctx context.Context, w types.Watcher) (*types.Watcher, error) {
+	query := `
+		UPDATE import_watchers SET
+			name = $3, destination_id = $4, mapping_id = $5, pattern = $6, archive_path = $7, error_emails = $8,
+			interval_minutes = $9, active = $10, next_poll_at = $11, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING ` + watcherColumns
+
+	watcher, err := scanWatcher(r.db.QueryRowContext(ctx, query,
+		w.OrganizationID, w.ID, w.Name, w.DestinationID, w.MappingID, w.Pattern, w.ArchivePath, pq.Array(w.ErrorEmails),
+		w.IntervalMinutes, w.Active, w.NextPollAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import watcher %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update import watcher: %w", err)
+	}
+	return watcher, nil
+}
+
+// DeleteWatcher deletes a watcher; its import log is kept
+func (r *ImportRepository) DeleteWatcher(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM import_watchers WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete import watcher: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("import watcher %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ListDueWatchers returns the watchers due to poll, longest overdue first
+func (r *ImportRepository) ListDueWatchers(ctx context.Context, now time.Time, limit int) ([]types.Watcher, error) {
+	return r.queryWatchers(ctx, `SELECT `+watcherColumns+` FROM import_watchers
+		WHERE active AND next_poll_at <= $1
+		ORDER BY next_poll_at, id
+		LIMIT $2`, now, limit)
+}
+
+// SetNextPoll moves a watcher's next poll
+func (r *ImportRepository) SetNextPoll(ctx context.Context, orgID, id uuid.UUID, next time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE import_watchers SET next_poll_at = $3 WHERE organization_id = $1 AND id = $2`, orgID, id, next)
+	if err != nil {
+		return fmt.Errorf("failed to schedule import watcher: %w", err)
+	}
+	return nil
+}
+
+// RecordPoll saves the outcome of a watcher's poll
+func (r *ImportRepository) RecordPoll(ctx context.Context, w types.Watcher) error {
+	query := `
+		UPDATE import_watchers SET
+			last_poll_at = $3, last_status = $4, last_error = NULLIF($5, ''), consecutive_failures = $6
+		WHERE organization_id = $1 AND id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, w.OrganizationID, w.ID, w.LastPollAt, w.LastStatus, w.LastError, w.ConsecutiveFailures)
+	if err != nil {
+		return fmt.Errorf("failed to record import poll: %w", err)
+	}
+	return nil
+}
+
+const importFileColumns = `id, organization_id, watcher_id, mapping_id, entity, remote_path, COALESCE(archived_path, ''),
+	size, COALESCE(sha256, ''), status, rows, created, updated, failed, COALESCE(error, ''), started_at, finished_at`
+
+func scanImportFile(row rowScanner) (*types.ImportFile, error) {
+	var f types.ImportFile
+	err := row.Scan(&f.ID, &f.OrganizationID, &f.WatcherID, &f.MappingID, &f.Entity, &f.RemotePath, &f.ArchivedPath,
+		&f.Size, &f.SHA256, &f.Status, &f.Rows, &f.Created, &f.Updated, &f.Failed, &f.Error, &f.StartedAt, &f.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// FindImportedFile returns the latest import of the file by the watcher
+// that read it
+func (r *ImportRepository) FindImportedFile(ctx context.Context, watcherID uuid.UUID, sha256 string) (*types.ImportFile, error) {
+	query := `SELECT ` + importFileColumns + ` FROM import_files
+		WHERE watcher_id = $1 AND sha256 = $2 AND status <> 'failed'
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	file, err := scanImportFile(r.db.QueryRowContext(ctx, query, watcherID, sha256))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import file %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import file: %w", err)
+	}
+	return file, nil
+}
+
+// CreateImportFile logs a file and its row errors in one transaction
+func (r *ImportRepository) CreateImportFile(ctx context.Context, f types.ImportFile) (*types.ImportFile, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO import_files (
+			id, organization_id, watcher_id, mapping_id, entity, remote_path, archived_path, size, sha256,
+			status, rows, created, updated, failed, error, started_at, finished_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), $10, $11, $12, $13, $14, NULLIF($15, ''), $16, $17)
+		RETURNING ` + importFileColumns
+
+	file, err := scanImportFile(tx.QueryRowContext(ctx, query,
+		f.ID, f.OrganizationID, f.WatcherID, f.MappingID, f.Entity, f.RemotePath, f.ArchivedPath, f.Size, f.SHA256,
+		f.Status, f.Rows, f.Created, f.Updated, f.Failed, f.Error, f.StartedAt, f.FinishedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to log import file: %w", err)
+	}
+
+	for _, rowErr := range f.Errors {
+		_, err := tx.ExecContext(ctx, `INSERT INTO import_row_errors (file_id, line, field, message) VALUES ($1, $2, NULLIF($3, ''), $4)`,
+			file.ID, rowErr.Line, rowErr.Field, rowErr.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to log import row error: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	file.Errors = f.Errors
+	return file, nil
+}
+
+// FindImportFile returns one of the organization's logged files with its
+// row errors
+func (r *ImportRepository) FindImportFile(ctx context.Context, orgID, id uuid.UUID) (*types.ImportFile, error) {
+	query := `SELECT ` + importFileColumns + ` FROM import_files WHERE organization_id = $1 AND id = $2`
+
+	file, err := scanImportFile(r.db.QueryRowContext(ctx, query, orgID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("import file %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import file: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT line, COALESCE(field, ''), message FROM import_row_errors
+		WHERE file_id = $1
+		ORDER BY line, id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import row errors: %w", err)
+	}
+	defer rows.Close()
+
+	file.Errors = []types.RowError{}
+	for rows.Next() {
+		var rowErr types.RowError
+		if err := rows.Scan(&rowErr.Line, &rowErr.Field, &rowErr.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan import row error: %w", err)
+		}
+		file.Errors = append(file.Errors, rowErr)
+	}
+	return file, rows.Err()
+}
+
+// ListImportFiles returns import log entries, latest first
+func (r *ImportRepository) ListImportFiles(ctx context.Context, filter types.ImportFileFilter) ([]types.ImportFile, error) {
+	query := `SELECT ` + importFileColumns + ` FROM import_files WHERE organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+	if filter.WatcherID != nil {
+		args = append(args, *filter.WatcherID)
+		query += fmt.Sprintf(" AND watcher_id = $%d", len(args))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY started_at DESC, id LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []types.ImportFile{}
+	for rows.Next() {
+		file, err := scanImportFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import file: %w", err)
+		}
+		files = append(files, *file)
+	}
+	return files, rows.Err()
+}
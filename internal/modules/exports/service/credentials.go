@@ -0,0 +1,76 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+)
+
+// SetCredentialKey sets the secret destination credentials are encrypted
+// with. Without it, destinations that need credentials cannot be saved or
+// used. Changing it makes the stored credentials unreadable; they must be
+// entered again.
+func (s *ExportService) SetCredentialKey(secret string) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		s.logger.Error("Failed to set export credential key", "error", err)
+		return
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		s.logger.Error("Failed to set export credential key", "error", err)
+		return
+	}
+	s.sealer = aead
+}
+
+// sealCredentials encrypts credentials for storage, as the nonce followed by
+// the ciphertext
+func (s *ExportService) sealCredentials(creds types.Credentials) ([]byte, error) {
+	if creds.IsEmpty() {
+		return nil, nil
+	}
+	if s.sealer == nil {
+		return nil, ErrCredentialsUnavailable
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.sealer.NonceSize(), s.sealer.NonceSize()+len(plaintext)+s.sealer.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to seal credentials: %w", err)
+	}
+	return s.sealer.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openCredentials decrypts a destination's credentials
+func (s *ExportService) openCredentials(destination types.Destination) (types.Credentials, error) {
+	var creds types.Credentials
+	if len(destination.SealedCredentials) == 0 {
+		return creds, nil
+	}
+	if s.sealer == nil {
+		return creds, ErrCredentialsUnavailable
+	}
+	sealed := destination.SealedCredentials
+	if len(sealed) < s.sealer.NonceSize() {
+		return creds, errors.New("stored credentials are corrupt")
+	}
+	nonce, ciphertext := sealed[:s.sealer.NonceSize()], sealed[s.sealer.NonceSize():]
+	plaintext, err := s.sealer.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return creds, errors.New("stored credentials cannot be decrypted; enter them again")
+	}
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return creds, errors.New("stored credentials are corrupt")
+	}
+	return creds, nil
+}
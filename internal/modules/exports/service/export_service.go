@@ -0,0 +1,707 @@
+package service
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RunInterval is how often due schedules are looked for; schedules run
+	// up to this long after their time
+	RunInterval = 5 * time.Minute
+	// runBatchSize bounds the schedules fetched per query
+	runBatchSize = 20
+	// transferTimeout bounds uploading one file
+	transferTimeout = 15 * time.Minute
+	// DefaultTransferLimit and MaxTransferLimit bound transfer log pages
+	DefaultTransferLimit = 50
+	MaxTransferLimit     = 500
+	// DefaultSFTPPort is used when a destination does not set a port
+	DefaultSFTPPort = 22
+	// EventTransferFailed is published when an export run fails
+	EventTransferFailed = "export.transfer_failed"
+)
+
+var (
+	// ErrInvalid wraps validation failures of destination and schedule requests
+	ErrInvalid = errors.New("invalid request")
+	// ErrCredentialsUnavailable is returned when credentials must be stored
+	// or read but no credential key is configured
+	ErrCredentialsUnavailable = errors.New("export credential storage is not configured")
+)
+
+// AuthService defines the permission check used by the export service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer sends failure alerts
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// Source is something schedules export: a built-in entity extract, or a
+// report registered by another module
+type Source interface {
+	// Columns returns the header row
+	Columns() []string
+	// Extract emits the organization's rows, only those changed after since
+	// when it is set. emit must not keep the row.
+	Extract(ctx context.Context, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error
+}
+
+type registeredSource struct {
+	name   string
+	source Source
+}
+
+// entitySource exports a built-in entity extract
+type entitySource struct {
+	repo    repository.ExportRepo
+	code    string
+	columns []string
+}
+
+func (e entitySource) Columns() []string {
+	return e.columns
+}
+
+func (e entitySource) Extract(ctx context.Context, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error {
+	return e.repo.ExtractEntity(ctx, e.code, orgID, since, emit)
+}
+
+// ExportService delivers scheduled CSV exports to organizations' SFTP
+// servers and S3 buckets, logs every transfer and alerts on failures
+type ExportService struct {
+	repo        repository.ExportRepo
+	authService AuthService
+	eventBus    *events.Bus
+	mailer      Mailer
+	sealer      cipher.AEAD
+	sources     map[string]registeredSource
+	transports  map[types.DestinationKind]Transport
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewExportService(repo repository.ExportRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *ExportService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &ExportService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		sources:     make(map[string]registeredSource),
+		transports: map[types.DestinationKind]Transport{
+			types.DestinationSFTP: &SFTPTransport{},
+			types.DestinationS3:   &S3Transport{},
+		},
+		logger: logger,
+		now:    time.Now,
+	}
+	for _, extract := range repository.EntityExtracts {
+		s.RegisterSource(extract.Code, extract.Name, entitySource{repo: repo, code: extract.Code, columns: extract.Columns})
+	}
+	return s
+}
+
+// RegisterSource makes a source available to schedules under code,
+// replacing any source registered under it
+func (s *ExportService) RegisterSource(code, name string, source Source) {
+	s.sources[code] = registeredSource{name: name, source: source}
+}
+
+// SetMailer sets the mailer failure alerts are sent with. Without one,
+// failures are only logged and published.
+func (s *ExportService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetTransport replaces the transport of a kind of destination
+func (s *ExportService) SetTransport(kind types.DestinationKind, transport Transport) {
+	s.transports[kind] = transport
+}
+
+func (s *ExportService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish export event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListSources returns the sources schedules can export, by code
+func (s *ExportService) ListSources(ctx context.Context) ([]types.SourceInfo, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	sources := make([]types.SourceInfo, 0, len(s.sources))
+	for code, registered := range s.sources {
+		sources = append(sources, types.SourceInfo{Code: code, Name: registered.name, Columns: registered.source.Columns()})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Code < sources[j].Code })
+	return sources, nil
+}
+
+// ListDestinations returns the organization's destinations
+func (s *ExportService) ListDestinations(ctx context.Context, orgID uuid.UUID) ([]types.Destination, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListDestinations(ctx, orgID)
+}
+
+// GetDestination returns a destination; its credentials are never returned
+func (s *ExportService) GetDestination(ctx context.Context, orgID, id uuid.UUID) (*types.Destination, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDestination(ctx, orgID, id)
+}
+
+// CreateDestination adds a destination with its credentials
+func (s *ExportService) CreateDestination(ctx context.Context, orgID, userID uuid.UUID, req types.DestinationRequest) (*types.Destination, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	destination := types.Destination{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Active:         true,
+		CreatedBy:      &userID,
+	}
+	if req.Credentials == nil {
+		req.Credentials = &types.Credentials{}
+	}
+	if err := s.applyDestination(&destination, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateDestination(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Export destination added", "organization_id", orgID, "destination_id", created.ID, "kind", created.Kind)
+	return created, nil
+}
+
+// UpdateDestination replaces a destination's settings. Stored credentials
+// are kept unless new ones are given, which they must be when the kind
+// changes.
+func (s *ExportService) UpdateDestination(ctx context.Context, orgID, id uuid.UUID, req types.DestinationRequest) (*types.Destination, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	destination, err := s.repo.FindDestination(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Credentials == nil && req.Kind != destination.Kind {
+		return nil, fmt.Errorf("%w: credentials are required when the kind changes", ErrInvalid)
+	}
+	if err := s.applyDestination(destination, req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateDestination(ctx, *destination)
+}
+
+// applyDestination validates req and copies it onto destination, sealing
+// new credentials
+func (s *ExportService) applyDestination(destination *types.Destination, req types.DestinationRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if !req.Kind.IsValid() {
+		return fmt.Errorf("%w: kind must be sftp or s3", ErrInvalid)
+	}
+
+	next := types.Destination{
+		ID:                destination.ID,
+		OrganizationID:    destination.OrganizationID,
+		Name:              name,
+		Kind:              req.Kind,
+		SealedCredentials: destination.SealedCredentials,
+		Active:            destination.Active,
+		CreatedBy:         destination.CreatedBy,
+		CreatedAt:         destination.CreatedAt,
+	}
+	if req.Active != nil {
+		next.Active = *req.Active
+	}
+
+	switch req.Kind {
+	case types.DestinationSFTP:
+		next.Host = strings.TrimSpace(req.Host)
+		next.Port = req.Port
+		next.Username = strings.TrimSpace(req.Username)
+		next.HostKey = strings.TrimSpace(req.HostKey)
+		// Relative paths are relative to the user's home directory
+		next.Path = path.Clean(strings.TrimSpace(req.Path))
+		if next.Port == 0 {
+			next.Port = DefaultSFTPPort
+		}
+		if next.Host == "" || next.Username == "" {
+			return fmt.Errorf("%w: host and username are required", ErrInvalid)
+		}
+		if next.Port < 1 || next.Port > 65535 {
+			return fmt.Errorf("%w: port must be between 1 and 65535", ErrInvalid)
+		}
+		if next.HostKey == "" {
+			return fmt.Errorf("%w: host_key is required to verify the server", ErrInvalid)
+		}
+		if req.Credentials != nil {
+			if err := validateSSHCredentials(next.HostKey, *req.Credentials); err != nil {
+				return err
+			}
+			req.Credentials.AccessKey, req.Credentials.SecretKey = "", ""
+		} else if _, err := hostKeyCallback(next.HostKey); err != nil {
+			return err
+		}
+	case types.DestinationS3:
+		next.Bucket = strings.TrimSpace(req.Bucket)
+		next.Region = strings.TrimSpace(req.Region)
+		next.Endpoint = strings.TrimSpace(req.Endpoint)
+		next.Path = strings.Trim(path.Clean("/"+strings.TrimSpace(req.Path)), "/")
+		if next.Bucket == "" {
+			return fmt.Errorf("%w: bucket is required", ErrInvalid)
+		}
+		if next.Region == "" {
+			return fmt.Errorf("%w: region is required", ErrInvalid)
+		}
+		if req.Credentials != nil {
+			if req.Credentials.AccessKey == "" || req.Credentials.SecretKey == "" {
+				return fmt.Errorf("%w: access_key and secret_key are required", ErrInvalid)
+			}
+			*req.Credentials = types.Credentials{AccessKey: req.Credentials.AccessKey, SecretKey: req.Credentials.SecretKey}
+		}
+	}
+
+	if req.Credentials != nil {
+		sealed, err := s.sealCredentials(*req.Credentials)
+		if err != nil {
+			return err
+		}
+		next.SealedCredentials = sealed
+	}
+	*destination = next
+	return nil
+}
+
+// DeleteDestination removes a destination no schedule or watcher uses
+func (s *ExportService) DeleteDestination(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteDestination(ctx, orgID, id)
+}
+
+// CheckDestination connects to a destination with its stored credentials
+// and reports whether files can be delivered to its path
+func (s *ExportService) CheckDestination(ctx context.Context, orgID, id uuid.UUID) (*types.DestinationCheck, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	destination, err := s.repo.FindDestination(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.openCredentials(*destination)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &types.DestinationCheck{OK: true}
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout*2)
+	defer cancel()
+	if err := s.transports[destination.Kind].Check(ctx, *destination, creds); err != nil {
+		check.OK = false
+		check.Error = err.Error()
+	}
+	check.CheckedAt = s.now()
+	return check, nil
+}
+
+// ListSchedules returns the organization's schedules
+func (s *ExportService) ListSchedules(ctx context.Context, orgID uuid.UUID) ([]types.Schedule, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListSchedules(ctx, orgID)
+}
+
+// GetSchedule returns a schedule with the outcome of its last run
+func (s *ExportService) GetSchedule(ctx context.Context, orgID, id uuid.UUID) (*types.Schedule, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindSchedule(ctx, orgID, id)
+}
+
+// CreateSchedule adds a schedule, first run at its next time
+func (s *ExportService) CreateSchedule(ctx context.Context, orgID, userID uuid.UUID, req types.ScheduleRequest) (*types.Schedule, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	schedule := types.Schedule{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Active:         true,
+		CreatedBy:      &userID,
+	}
+	if err := s.applySchedule(ctx, &schedule, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateSchedule(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Export schedule added", "organization_id", orgID, "schedule_id", created.ID, "source", created.Source)
+	return created, nil
+}
+
+// UpdateSchedule replaces a schedule's settings and reschedules it. The
+// run history, including where incremental exports resume, is kept.
+func (s *ExportService) UpdateSchedule(ctx context.Context, orgID, id uuid.UUID, req types.ScheduleRequest) (*types.Schedule, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	schedule, err := s.repo.FindSchedule(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySchedule(ctx, schedule, req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateSchedule(ctx, *schedule)
+}
+
+// applySchedule validates req and copies it onto schedule
+func (s *ExportService) applySchedule(ctx context.Context, schedule *types.Schedule, req types.ScheduleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if _, ok := s.sources[req.Source]; !ok {
+		return fmt.Errorf("%w: unknown source %q", ErrInvalid, req.Source)
+	}
+	if _, err := s.repo.FindDestination(ctx, schedule.OrganizationID, req.DestinationID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: destination does not exist", ErrInvalid)
+		}
+		return err
+	}
+	if !req.Frequency.IsValid() {
+		return fmt.Errorf("%w: frequency must be daily, weekly or monthly", ErrInvalid)
+	}
+	if _, err := time.Parse("15:04", req.TimeOfDay); err != nil {
+		return fmt.Errorf("%w: time_of_day must be HH:MM", ErrInvalid)
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalid, timezone)
+	}
+
+	filename := strings.TrimSpace(req.Filename)
+	if filename == "" {
+		filename = req.Source + "_{date}.csv"
+	}
+	if strings.ContainsAny(filename, "/\\") || filename == "." || filename == ".." {
+		return fmt.Errorf("%w: filename must not contain a path", ErrInvalid)
+	}
+
+	var weekday, dayOfMonth *int
+	switch req.Frequency {
+	case types.FrequencyWeekly:
+		if req.Weekday == nil || *req.Weekday < 0 || *req.Weekday > 6 {
+			return fmt.Errorf("%w: weekly schedules need a weekday from 0 (Sunday) to 6", ErrInvalid)
+		}
+		weekday = req.Weekday
+	case types.FrequencyMonthly:
+		if req.DayOfMonth == nil || *req.DayOfMonth < 1 || *req.DayOfMonth > 28 {
+			return fmt.Errorf("%w: monthly schedules need a day_of_month from 1 to 28", ErrInvalid)
+		}
+		dayOfMonth = req.DayOfMonth
+	}
+
+	alertEmails := make([]string, 0, len(req.AlertEmails))
+	for _, email := range req.AlertEmails {
+		address, err := mail.ParseAddress(strings.TrimSpace(email))
+		if err != nil {
+			return fmt.Errorf("%w: invalid alert email %q", ErrInvalid, email)
+		}
+		alertEmails = append(alertEmails, address.Address)
+	}
+
+	schedule.Name = name
+	schedule.DestinationID = req.DestinationID
+	schedule.Source = req.Source
+	schedule.Filename = filename
+	schedule.Incremental = req.Incremental
+	schedule.Frequency = req.Frequency
+	schedule.TimeOfDay = req.TimeOfDay
+	schedule.Weekday = weekday
+	schedule.DayOfMonth = dayOfMonth
+	schedule.Timezone = timezone
+	schedule.AlertEmails = alertEmails
+	if req.Active != nil {
+		schedule.Active = *req.Active
+	}
+	schedule.NextRunAt = nil
+	if schedule.Active {
+		next := nextRun(*schedule, s.now())
+		schedule.NextRunAt = &next
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule; its transfer log is kept
+func (s *ExportService) DeleteSchedule(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteSchedule(ctx, orgID, id)
+}
+
+// ListTransfers returns the transfer log, latest first
+func (s *ExportService) ListTransfers(ctx context.Context, filter types.TransferFilter) ([]types.Transfer, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTransferLimit
+	}
+	if filter.Limit > MaxTransferLimit {
+		filter.Limit = MaxTransferLimit
+	}
+	return s.repo.ListTransfers(ctx, filter)
+}
+
+// RunNow runs a schedule immediately, outside its schedule. The returned
+// transfer records whether it succeeded.
+func (s *ExportService) RunNow(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Transfer, error) {
+	if err := s.authService.CheckPermission(ctx, "exports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	schedule, err := s.repo.FindSchedule(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, *schedule, types.TriggerManual, &userID)
+}
+
+// RunDue runs the schedules whose time has come. Each is moved to its next
+// time before it runs, so a schedule that fails, or a worker that crashes
+// mid-run, waits for its next time rather than retrying in a loop.
+func (s *ExportService) RunDue(ctx context.Context) (*types.ExportRun, error) {
+	run := &types.ExportRun{}
+	for {
+		now := s.now()
+		schedules, err := s.repo.ListDueSchedules(ctx, now, runBatchSize)
+		if err != nil {
+			return run, err
+		}
+		moved := 0
+		for _, schedule := range schedules {
+			if err := s.repo.SetNextRun(ctx, schedule.OrganizationID, schedule.ID, nextRun(schedule, now)); err != nil {
+				s.logger.Warn("Failed to reschedule export", "schedule_id", schedule.ID, "error", err)
+				continue
+			}
+			moved++
+			transfer, err := s.run(ctx, schedule, types.TriggerScheduled, nil)
+			if err != nil {
+				s.logger.Warn("Failed to record export run", "schedule_id", schedule.ID, "error", err)
+			}
+			run.Ran++
+			if transfer == nil || transfer.Status == types.TransferFailed {
+				run.Failed++
+			}
+		}
+		// Schedules that failed to move are still due; stop rather than
+		// fetching them again
+		if len(schedules) < runBatchSize || moved == 0 {
+			return run, nil
+		}
+	}
+}
+
+// run exports the schedule's source, logs the transfer and records the
+// outcome on the schedule. The returned error is about recording; a failed
+// export is a transfer with the failed status.
+func (s *ExportService) run(ctx context.Context, schedule types.Schedule, trigger types.TransferTrigger, userID *uuid.UUID) (*types.Transfer, error) {
+	started := s.now()
+	transfer := types.Transfer{
+		ID:             uuid.New(),
+		OrganizationID: schedule.OrganizationID,
+		ScheduleID:     schedule.ID,
+		DestinationID:  schedule.DestinationID,
+		Source:         schedule.Source,
+		Trigger:        trigger,
+		Status:         types.TransferSucceeded,
+		StartedAt:      started,
+		TriggeredBy:    userID,
+	}
+	if schedule.Incremental {
+		transfer.Since = schedule.LastSuccessAt
+	}
+
+	if err := s.transfer(ctx, schedule, &transfer); err != nil {
+		transfer.Status = types.TransferFailed
+		transfer.Error = err.Error()
+	}
+	transfer.FinishedAt = s.now()
+
+	status := transfer.Status
+	schedule.LastRunAt = &started
+	schedule.LastStatus = &status
+	if status == types.TransferSucceeded {
+		schedule.LastSuccessAt = &started
+		schedule.ConsecutiveFailures = 0
+		s.logger.Info("Export delivered", "schedule_id", schedule.ID, "remote_path", transfer.RemotePath, "rows", transfer.Rows)
+	} else {
+		schedule.ConsecutiveFailures++
+		s.logger.Warn("Export failed", "schedule_id", schedule.ID, "error", transfer.Error)
+		s.alert(ctx, schedule, transfer)
+	}
+
+	logged, err := s.repo.CreateTransfer(ctx, transfer)
+	if err != nil {
+		return &transfer, err
+	}
+	if err := s.repo.RecordRun(ctx, schedule); err != nil {
+		return logged, err
+	}
+	return logged, nil
+}
+
+// transfer writes the source's rows to a temporary CSV file and sends it
+// to the destination
+func (s *ExportService) transfer(ctx context.Context, schedule types.Schedule, transfer *types.Transfer) error {
+	registered, ok := s.sources[schedule.Source]
+	if !ok {
+		return fmt.Errorf("source %q is no longer available", schedule.Source)
+	}
+	destination, err := s.repo.FindDestination(ctx, schedule.OrganizationID, schedule.DestinationID)
+	if err != nil {
+		return err
+	}
+	if !destination.Active {
+		return fmt.Errorf("destination %q is inactive", destination.Name)
+	}
+	creds, err := s.openCredentials(*destination)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "export-*.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, hash)}
+	w := csv.NewWriter(counter)
+	if err := w.Write(registered.source.Columns()); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	err = registered.source.Extract(ctx, schedule.OrganizationID, transfer.Since, func(row []string) error {
+		transfer.Rows++
+		return w.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	transfer.Size = counter.n
+	transfer.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read export file: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+	remotePath, err := s.transports[destination.Kind].Send(ctx, *destination, creds, remoteName(schedule, transfer.StartedAt), file, transfer.Size)
+	if err != nil {
+		return err
+	}
+	transfer.RemotePath = remotePath
+	return nil
+}
+
+// alert emails the schedule's alert addresses and publishes the failure
+func (s *ExportService) alert(ctx context.Context, schedule types.Schedule, transfer types.Transfer) {
+	s.publishEvent(ctx, EventTransferFailed, map[string]interface{}{
+		"organization_id":      schedule.OrganizationID,
+		"schedule_id":          schedule.ID,
+		"transfer_id":          transfer.ID,
+		"error":                transfer.Error,
+		"consecutive_failures": schedule.ConsecutiveFailures,
+	})
+	if s.mailer == nil {
+		return
+	}
+
+	subject := fmt.Sprintf("Export %q failed", schedule.Name)
+	body := fmt.Sprintf("The %s export %q failed at %s:\n\n%s\n\n",
+		schedule.Source, schedule.Name, transfer.StartedAt.UTC().Format(time.RFC1123), transfer.Error)
+	if schedule.ConsecutiveFailures > 1 {
+		body += fmt.Sprintf("It has failed %d times in a row.\n", schedule.ConsecutiveFailures)
+	}
+	if schedule.Incremental {
+		body += "The next successful run will include the rows this run missed.\n"
+	}
+	for _, to := range schedule.AlertEmails {
+		if err := s.mailer.Send(metering.WithOrganization(ctx, schedule.OrganizationID), to, subject, body); err != nil {
+			s.logger.Warn("Failed to send export failure alert", "schedule_id", schedule.ID, "to", to, "error", err)
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExportRepo struct {
+	destinations map[uuid.UUID]types.Destination
+	schedules    map[uuid.UUID]types.Schedule
+	transfers    []types.Transfer
+}
+
+func newFakeExportRepo() *fakeExportRepo {
+	return &fakeExportRepo{
+		destinations: make(map[uuid.UUID]types.Destination),
+		schedules:    make(map[uuid.UUID]types.Schedule),
+	}
+}
+
+func (f *fakeExportRepo) ListDestinations(ctx context.Context, orgID uuid.UUID) ([]types.Destination, error) {
+	var destinations []types.Destination
+	for _, d := range f.destinations {
+		if d.OrganizationID == orgID {
+			destinations = append(destinations, d)
+		}
+	}
+	return destinations, nil
+}
+
+func (f *fakeExportRepo) FindDestination(ctx context.Context, orgID, id uuid.UUID) (*types.Destination, error) {
+	d, ok := f.destinations[id]
+	if !ok || d.OrganizationID != orgID {
+		return nil, fmt.Errorf("export destination %w", repository.ErrNotFound)
+	}
+	d.HasCredentials = len(d.SealedCredentials) > 0
+	return &d, nil
+}
+
+func (f *fakeExportRepo) CreateDestination(ctx context.Context, destination types.Destination) (*types.Destination, error) {
+	f.destinations[destination.ID] = destination
+	return f.FindDestination(ctx, destination.OrganizationID, destination.ID)
+}
+
+func (f *fakeExportRepo) UpdateDestination(ctx context.Context, destination types.Destination) (*types.Destination, error) {
+	return f.CreateDestination(ctx, destination)
+}
+
+func (f *fakeExportRepo) DeleteDestination(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.destinations, id)
+	return nil
+}
+
+func (f *fakeExportRepo) ListSchedules(ctx context.Context, orgID uuid.UUID) ([]types.Schedule, error) {
+	var schedules []types.Schedule
+	for _, s := range f.schedules {
+		if s.OrganizationID == orgID {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+func (f *fakeExportRepo) FindSchedule(ctx context.Context, orgID, id uuid.UUID) (*types.Schedule, error) {
+	s, ok := f.schedules[id]
+	if !ok || s.OrganizationID != orgID {
+		return nil, fmt.Errorf("export schedule %w", repository.ErrNotFound)
+	}
+	return &s, nil
+}
+
+func (f *fakeExportRepo) CreateSchedule(ctx context.Context, schedule types.Schedule) (*types.Schedule, error) {
+	f.schedules[schedule.ID] = schedule
+	return f.FindSchedule(ctx, schedule.OrganizationID, schedule.ID)
+}
+
+func (f *fakeExportRepo) UpdateSchedule(ctx context.Context, schedule types.Schedule) (*types.Schedule, error) {
+	return f.CreateSchedule(ctx, schedule)
+}
+
+func (f *fakeExportRepo) DeleteSchedule(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.schedules, id)
+	return nil
+}
+
+func (f *fakeExportRepo) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]types.Schedule, error) {
+	var schedules []types.Schedule
+	for _, s := range f.schedules {
+		if s.Active && s.NextRunAt != nil && !s.NextRunAt.After(now) {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+func (f *fakeExportRepo) SetNextRun(ctx context.Context, orgID, id uuid.UUID, next time.Time) error {
+	s := f.schedules[id]
+	s.NextRunAt = &next
+	f.schedules[id] = s
+	return nil
+}
+
+func (f *fakeExportRepo) RecordRun(ctx context.Context, schedule types.Schedule) error {
+	s := f.schedules[schedule.ID]
+	s.LastRunAt = schedule.LastRunAt
+	s.LastStatus = schedule.LastStatus
+	s.LastSuccessAt = schedule.LastSuccessAt
+	s.ConsecutiveFailures = schedule.ConsecutiveFailures
+	f.schedules[schedule.ID] = s
+	return nil
+}
+
+func (f *fakeExportRepo) CreateTransfer(ctx context.Context, transfer types.Transfer) (*types.Transfer, error) {
+	f.transfers = append(f.transfers, transfer)
+	return &transfer, nil
+}
+
+func (f *fakeExportRepo) ListTransfers(ctx context.Context, filter types.TransferFilter) ([]types.Transfer, error) {
+	return f.transfers, nil
+}
+
+func (f *fakeExportRepo) ExtractEntity(ctx context.Context, entity string, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error {
+	return fmt.Errorf("entity extract %s %w", entity, repository.ErrNotFound)
+}
+
+// fakeSource exports customers, each changed at a time
+type fakeSource struct {
+	rows  map[string]time.Time
+	since []*time.Time
+}
+
+func (f *fakeSource) Columns() []string {
+	return []string{"name", "note"}
+}
+
+func (f *fakeSource) Extract(ctx context.Context, orgID uuid.UUID, since *time.Time, emit func(row []string) error) error {
+	f.since = append(f.since, since)
+	for _, name := range []string{"Acme", "Globex, Inc."} {
+		if changed, ok := f.rows[name]; ok && (since == nil || changed.After(*since)) {
+			if err := emit([]string{name, `said "hi"`}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type sentFile struct {
+	name    string
+	content string
+	creds   types.Credentials
+}
+
+type fakeTransport struct {
+	sent []sentFile
+	err  error
+	// files are the remote files by path, listed by List
+	files map[string]types.RemoteFile
+	data  map[string]string
+	moved map[string]string
+}
+
+func (f *fakeTransport) put(file types.RemoteFile, content string) {
+	if f.files == nil {
+		f.files = make(map[string]types.RemoteFile)
+		f.data = make(map[string]string)
+	}
+	file.Size = int64(len(content))
+	f.files[file.Path] = file
+	f.data[file.Path] = content
+}
+
+func (f *fakeTransport) Send(ctx context.Context, destination types.Destination, creds types.Credentials, name string, r io.Reader, size int64) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(content)) != size {
+		return "", fmt.Errorf("read %d of %d bytes", len(content), size)
+	}
+	f.sent = append(f.sent, sentFile{name: name, content: string(content), creds: creds})
+	return destination.Path + "/" + name, nil
+}
+
+func (f *fakeTransport) Check(ctx context.Context, destination types.Destination, creds types.Credentials) error {
+	return f.err
+}
+
+func (f *fakeTransport) List(ctx context.Context, destination types.Destination, creds types.Credentials) ([]types.RemoteFile, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var files []types.RemoteFile
+	for _, file := range f.files {
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (f *fakeTransport) Fetch(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath string, w io.Writer) (int64, error) {
+	content, ok := f.data[remotePath]
+	if !ok {
+		return 0, fmt.Errorf("%s: no such file", remotePath)
+	}
+	n, err := io.WriteString(w, content)
+	return int64(n), err
+}
+
+func (f *fakeTransport) Move(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath, target string) error {
+	if _, ok := f.files[remotePath]; !ok {
+		return fmt.Errorf("%s: no such file", remotePath)
+	}
+	if f.moved == nil {
+		f.moved = make(map[string]string)
+	}
+	f.moved[remotePath] = target
+	delete(f.files, remotePath)
+	delete(f.data, remotePath)
+	return nil
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+type fakeMailer struct {
+	sent []sentMail
+}
+
+func (f *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	f.sent = append(f.sent, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+const testHostKey = "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"
+
+func newTestService(repo *fakeExportRepo, transport *fakeTransport, clock *time.Time) *ExportService {
+	svc := NewExportService(repo, allowAll{}, nil, nil)
+	svc.SetTransport(types.DestinationSFTP, transport)
+	svc.SetCredentialKey("test-secret")
+	svc.now = func() time.Time { return *clock }
+	return svc
+}
+
+func TestScheduledExportDeliversIncrementalCSV(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC)
+	repo := newFakeExportRepo()
+	transport := &fakeTransport{}
+	svc := newTestService(repo, transport, &clock)
+	source := &fakeSource{rows: map[string]time.Time{"Acme": clock.Add(-time.Hour), "Globex, Inc.": clock.Add(-time.Hour)}}
+	svc.RegisterSource("customers", "Customers", source)
+	orgID := uuid.New()
+
+	destination, err := svc.CreateDestination(ctx, orgID, uuid.New(), types.DestinationRequest{
+		Name: "Acme SFTP", Kind: types.DestinationSFTP, Host: "sftp.example.com", Username: "erp",
+		HostKey: testHostKey, Path: "/upload/", Credentials: &types.Credentials{Password: "s3cret", AccessKey: "ignored"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 22, destination.Port)
+	assert.Equal(t, "/upload", destination.Path)
+	assert.True(t, destination.HasCredentials)
+	assert.NotContains(t, string(repo.destinations[destination.ID].SealedCredentials), "s3cret")
+
+	// 02:00 in New York, which is 06:00 UTC on 11 March after the clocks changed
+	schedule, err := svc.CreateSchedule(ctx, orgID, uuid.New(), types.ScheduleRequest{
+		Name: "Nightly customers", DestinationID: destination.ID, Source: "customers", Filename: "customers_{date}.csv",
+		Incremental: true, Frequency: types.FrequencyDaily, TimeOfDay: "02:00", Timezone: "America/New_York",
+		AlertEmails: []string{"Ops <ops@example.com>"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, schedule.NextRunAt)
+	assert.Equal(t, time.Date(2025, 3, 11, 6, 0, 0, 0, time.UTC), schedule.NextRunAt.UTC())
+	assert.Equal(t, []string{"ops@example.com"}, schedule.AlertEmails)
+
+	run, err := svc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Ran)
+
+	clock = time.Date(2025, 3, 11, 6, 3, 0, 0, time.UTC)
+	run, err = svc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Ran)
+	assert.Equal(t, 0, run.Failed)
+
+	require.Len(t, transport.sent, 1)
+	assert.Equal(t, "customers_2025-03-11.csv", transport.sent[0].name)
+	assert.Equal(t, "name,note\nAcme,\"said \"\"hi\"\"\"\n\"Globex, Inc.\",\"said \"\"hi\"\"\"\n", transport.sent[0].content)
+	assert.Equal(t, types.Credentials{Password: "s3cret"}, transport.sent[0].creds)
+	assert.Nil(t, source.since[0])
+
+	require.Len(t, repo.transfers, 1)
+	transfer := repo.transfers[0]
+	assert.Equal(t, types.TransferSucceeded, transfer.Status)
+	assert.Equal(t, types.TriggerScheduled, transfer.Trigger)
+	assert.Equal(t, "/upload/customers_2025-03-11.csv", transfer.RemotePath)
+	assert.Equal(t, 2, transfer.Rows)
+	assert.Equal(t, int64(len(transport.sent[0].content)), transfer.Size)
+	assert.Len(t, transfer.SHA256, 64)
+
+	saved := repo.schedules[schedule.ID]
+	assert.Equal(t, time.Date(2025, 3, 12, 6, 0, 0, 0, time.UTC), saved.NextRunAt.UTC())
+	assert.Equal(t, clock, *saved.LastSuccessAt)
+
+	// The next run only exports what changed since the last one started
+	source.rows["Acme"] = clock.Add(time.Hour)
+	clock = clock.Add(2 * time.Hour)
+	transferred, err := svc.RunNow(ctx, orgID, uuid.New(), schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.TriggerManual, transferred.Trigger)
+	assert.Equal(t, 1, transferred.Rows)
+	require.NotNil(t, transferred.Since)
+	assert.Equal(t, time.Date(2025, 3, 11, 6, 3, 0, 0, time.UTC), *transferred.Since)
+	assert.Equal(t, "name,note\nAcme,\"said \"\"hi\"\"\"\n", transport.sent[1].content)
+}
+
+func TestFailedExportAlerts(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC)
+	repo := newFakeExportRepo()
+	transport := &fakeTransport{err: errors.New("connection refused")}
+	svc := newTestService(repo, transport, &clock)
+	mailer := &fakeMailer{}
+	svc.SetMailer(mailer)
+	svc.RegisterSource("customers", "Customers", &fakeSource{rows: map[string]time.Time{"Acme": clock}})
+	orgID := uuid.New()
+
+	destination, err := svc.CreateDestination(ctx, orgID, uuid.New(), types.DestinationRequest{
+		Name: "Acme SFTP", Kind: types.DestinationSFTP, Host: "sftp.example.com", Username: "erp",
+		HostKey: testHostKey, Credentials: &types.Credentials{Password: "s3cret"},
+	})
+	require.NoError(t, err)
+	weekday := int(time.Monday)
+	schedule, err := svc.CreateSchedule(ctx, orgID, uuid.New(), types.ScheduleRequest{
+		Name: "Weekly customers", DestinationID: destination.ID, Source: "customers", Frequency: types.FrequencyWeekly,
+		Weekday: &weekday, TimeOfDay: "07:30", AlertEmails: []string{"ops@example.com", "it@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "customers_{date}.csv", schedule.Filename)
+	assert.Equal(t, time.Date(2025, 3, 17, 7, 30, 0, 0, time.UTC), *schedule.NextRunAt)
+
+	for i := 0; i < 2; i++ {
+		clock = schedule.NextRunAt.Add(time.Minute).AddDate(0, 0, 7*i)
+		run, err := svc.RunDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, run.Failed)
+	}
+
+	saved := repo.schedules[schedule.ID]
+	assert.Equal(t, types.TransferFailed, *saved.LastStatus)
+	assert.Equal(t, 2, saved.ConsecutiveFailures)
+	assert.Nil(t, saved.LastSuccessAt)
+	assert.Equal(t, time.Date(2025, 3, 31, 7, 30, 0, 0, time.UTC), *saved.NextRunAt)
+	require.Len(t, repo.transfers, 2)
+	assert.Equal(t, "connection refused", repo.transfers[1].Error)
+
+	require.Len(t, mailer.sent, 4)
+	assert.Equal(t, "it@example.com", mailer.sent[3].to)
+	assert.Equal(t, `Export "Weekly customers" failed`, mailer.sent[3].subject)
+	assert.Contains(t, mailer.sent[3].body, "connection refused")
+	assert.Contains(t, mailer.sent[3].body, "failed 2 times in a row")
+}
+
+func TestDestinationValidation(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC)
+	repo := newFakeExportRepo()
+	orgID := uuid.New()
+	req := types.DestinationRequest{
+		Name: "Acme SFTP", Kind: types.DestinationSFTP, Host: "sftp.example.com", Username: "erp",
+		HostKey: testHostKey, Credentials: &types.Credentials{Password: "s3cret"},
+	}
+
+	// Credentials are never stored unencrypted
+	svc := NewExportService(repo, allowAll{}, nil, nil)
+	_, err := svc.CreateDestination(ctx, orgID, uuid.New(), req)
+	assert.ErrorIs(t, err, ErrCredentialsUnavailable)
+
+	svc = newTestService(repo, &fakeTransport{}, &clock)
+	unpinned := req
+	unpinned.HostKey = ""
+	_, err = svc.CreateDestination(ctx, orgID, uuid.New(), unpinned)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	badKey := req
+	badKey.Credentials = &types.Credentials{PrivateKey: "not a key"}
+	_, err = svc.CreateDestination(ctx, orgID, uuid.New(), badKey)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	destination, err := svc.CreateDestination(ctx, orgID, uuid.New(), req)
+	require.NoError(t, err)
+
+	// Updating without credentials keeps them; switching to S3 needs new ones
+	req.Credentials = nil
+	req.Name = "Acme upload"
+	updated, err := svc.UpdateDestination(ctx, orgID, destination.ID, req)
+	require.NoError(t, err)
+	assert.True(t, updated.HasCredentials)
+	creds, err := svc.openCredentials(repo.destinations[destination.ID])
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", creds.Password)
+
+	_, err = svc.UpdateDestination(ctx, orgID, destination.ID, types.DestinationRequest{
+		Name: "Acme bucket", Kind: types.DestinationS3, Bucket: "acme", Region: "us-east-1",
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// A different key cannot read the stored credentials
+	svc.SetCredentialKey("another-secret")
+	_, err = svc.openCredentials(repo.destinations[destination.ID])
+	assert.Error(t, err)
+}
+
+func TestNextRunMonthly(t *testing.T) {
+	day := 28
+	schedule := types.Schedule{Frequency: types.FrequencyMonthly, DayOfMonth: &day, TimeOfDay: "23:15", Timezone: "Europe/Paris"}
+
+	next := nextRun(schedule, time.Date(2025, 1, 28, 22, 15, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 2, 28, 22, 15, 0, 0, time.UTC), next.UTC())
+
+	next = nextRun(schedule, time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 1, 28, 22, 15, 0, 0, time.UTC), next.UTC())
+}
@@ -0,0 +1,831 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/mail"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// WatchInterval is how often due watchers are looked for
+	WatchInterval = time.Minute
+	// watchBatchSize bounds the watchers fetched per query
+	watchBatchSize = 20
+	// DefaultWatchMinutes, MinWatchMinutes and MaxWatchMinutes bound how
+	// often a watcher polls
+	DefaultWatchMinutes = 15
+	MinWatchMinutes     = 5
+	MaxWatchMinutes     = 1440
+	// DefaultImportPattern and DefaultArchivePath are used when a watcher
+	// does not set them
+	DefaultImportPattern = "*.csv"
+	DefaultArchivePath   = "archive"
+	// MaxImportFileSize bounds the files watchers download
+	MaxImportFileSize = 50 << 20
+	// maxFilesPerPoll bounds the files imported per poll; the rest wait for
+	// the next one
+	maxFilesPerPoll = 20
+	// fileSettleTime is how long a file must be left unchanged before it is
+	// imported, so that files still being uploaded are not
+	fileSettleTime = time.Minute
+	// maxRowErrors bounds the row errors logged per file, and
+	// mailedRowErrors those listed in the error email
+	maxRowErrors    = 1000
+	mailedRowErrors = 50
+	// DefaultImportFileLimit and MaxImportFileLimit bound import log pages
+	DefaultImportFileLimit = 50
+	MaxImportFileLimit     = 500
+	// EventFileImported is published when a watcher has imported a file
+	EventFileImported = "import.file_imported"
+	// EventWatchFailed is published when a watcher cannot reach its files
+	EventWatchFailed = "import.watch_failed"
+)
+
+// ImportService polls export destinations for CSV files, imports them into
+// entities through saved mappings, archives them and emails their row
+// errors. Destinations, their credentials and transports, and the mailer
+// are the export service's.
+type ImportService struct {
+	repo        repository.ImportRepo
+	exports     *ExportService
+	authService AuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+}
+
+func NewImportService(repo repository.ImportRepo, exports *ExportService, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *ImportService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ImportService{
+		repo:        repo,
+		exports:     exports,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+	}
+}
+
+func (s *ImportService) now() time.Time {
+	return s.exports.now()
+}
+
+func (s *ImportService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish import event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListEntities returns the entities files can be imported into, by code
+func (s *ImportService) ListEntities(ctx context.Context) ([]types.ImportEntityInfo, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	entities := make([]types.ImportEntityInfo, 0, len(repository.EntityLoads))
+	for _, load := range repository.EntityLoads {
+		entities = append(entities, types.ImportEntityInfo{Code: load.Code, Name: load.Name, Fields: load.Fields})
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Code < entities[j].Code })
+	return entities, nil
+}
+
+// ListMappings returns the organization's mappings
+func (s *ImportService) ListMappings(ctx context.Context, orgID uuid.UUID) ([]types.Mapping, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListMappings(ctx, orgID)
+}
+
+// GetMapping returns a mapping
+func (s *ImportService) GetMapping(ctx context.Context, orgID, id uuid.UUID) (*types.Mapping, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindMapping(ctx, orgID, id)
+}
+
+// CreateMapping saves a mapping of CSV columns onto an entity's fields
+func (s *ImportService) CreateMapping(ctx context.Context, orgID, userID uuid.UUID, req types.MappingRequest) (*types.Mapping, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	mapping := types.Mapping{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		CreatedBy:      &userID,
+	}
+	if err := applyMapping(&mapping, req); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateMapping(ctx, mapping)
+}
+
+// UpdateMapping replaces a mapping; watchers import with it from their
+// next poll
+func (s *ImportService) UpdateMapping(ctx context.Context, orgID, id uuid.UUID, req types.MappingRequest) (*types.Mapping, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	mapping, err := s.repo.FindMapping(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMapping(mapping, req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateMapping(ctx, *mapping)
+}
+
+// applyMapping validates req and copies it onto mapping
+func applyMapping(mapping *types.Mapping, req types.MappingRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	load, ok := repository.FindEntityLoad(req.Entity)
+	if !ok {
+		return fmt.Errorf("%w: unknown entity %q", ErrInvalid, req.Entity)
+	}
+	if len(req.Columns) == 0 {
+		return fmt.Errorf("%w: columns must map at least one CSV column", ErrInvalid)
+	}
+
+	columns := make(map[string]string, len(req.Columns))
+	headers := make(map[string]bool, len(req.Columns))
+	mapped := make(map[string]string, len(req.Columns))
+	for header, fieldName := range req.Columns {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			return fmt.Errorf("%w: column names must not be blank", ErrInvalid)
+		}
+		if headers[strings.ToLower(header)] {
+			return fmt.Errorf("%w: column %q is mapped more than once", ErrInvalid, header)
+		}
+		if _, ok := load.Field(fieldName); !ok {
+			return fmt.Errorf("%w: %s have no field %q", ErrInvalid, load.Name, fieldName)
+		}
+		if other, ok := mapped[fieldName]; ok {
+			return fmt.Errorf("%w: columns %q and %q are both mapped to %s", ErrInvalid, other, header, fieldName)
+		}
+		headers[strings.ToLower(header)] = true
+		mapped[fieldName] = header
+		columns[header] = fieldName
+	}
+
+	if req.MatchField != "" {
+		field, ok := load.Field(req.MatchField)
+		if !ok || !field.Matchable {
+			return fmt.Errorf("%w: %s cannot be matched on %q", ErrInvalid, load.Name, req.MatchField)
+		}
+		if _, ok := mapped[req.MatchField]; !ok {
+			return fmt.Errorf("%w: no column is mapped to the match field %s", ErrInvalid, req.MatchField)
+		}
+	}
+
+	mapping.Name = name
+	mapping.Entity = load.Code
+	mapping.Columns = columns
+	mapping.MatchField = req.MatchField
+	return nil
+}
+
+// DeleteMapping removes a mapping no watcher imports with
+func (s *ImportService) DeleteMapping(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteMapping(ctx, orgID, id)
+}
+
+// ListWatchers returns the organization's watchers
+func (s *ImportService) ListWatchers(ctx context.Context, orgID uuid.UUID) ([]types.Watcher, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListWatchers(ctx, orgID)
+}
+
+// GetWatcher returns a watcher with the outcome of its last poll
+func (s *ImportService) GetWatcher(ctx context.Context, orgID, id uuid.UUID) (*types.Watcher, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindWatcher(ctx, orgID, id)
+}
+
+// CreateWatcher adds a watcher, first polled on the next pass
+func (s *ImportService) CreateWatcher(ctx context.Context, orgID, userID uuid.UUID, req types.WatcherRequest) (*types.Watcher, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	watcher := types.Watcher{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Active:         true,
+		CreatedBy:      &userID,
+	}
+	if err := s.applyWatcher(ctx, &watcher, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateWatcher(ctx, watcher)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Import watcher added", "organization_id", orgID, "watcher_id", created.ID, "destination_id", created.DestinationID)
+	return created, nil
+}
+
+// UpdateWatcher replaces a watcher's settings and polls it on the next pass
+func (s *ImportService) UpdateWatcher(ctx context.Context, orgID, id uuid.UUID, req types.WatcherRequest) (*types.Watcher, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	watcher, err := s.repo.FindWatcher(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyWatcher(ctx, watcher, req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateWatcher(ctx, *watcher)
+}
+
+// applyWatcher validates req and copies it onto watcher
+func (s *ImportService) applyWatcher(ctx context.Context, watcher *types.Watcher, req types.WatcherRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if _, err := s.exports.repo.FindDestination(ctx, watcher.OrganizationID, req.DestinationID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: destination does not exist", ErrInvalid)
+		}
+		return err
+	}
+	if _, err := s.repo.FindMapping(ctx, watcher.OrganizationID, req.MappingID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: mapping does not exist", ErrInvalid)
+		}
+		return err
+	}
+
+	pattern := strings.TrimSpace(req.Pattern)
+	if pattern == "" {
+		pattern = DefaultImportPattern
+	}
+	if _, err := path.Match(pattern, ""); err != nil || strings.Contains(pattern, "/") {
+		return fmt.Errorf("%w: pattern must be a file name pattern such as *.csv", ErrInvalid)
+	}
+
+	archivePath := strings.TrimSpace(req.ArchivePath)
+	if archivePath == "" {
+		archivePath = DefaultArchivePath
+	}
+	archivePath = path.Clean(archivePath)
+	if path.IsAbs(archivePath) || archivePath == "." || archivePath == ".." || strings.HasPrefix(archivePath, "../") {
+		return fmt.Errorf("%w: archive_path must be a directory inside the destination's path", ErrInvalid)
+	}
+
+	interval := req.IntervalMinutes
+	if interval == 0 {
+		interval = DefaultWatchMinutes
+	}
+	if interval < MinWatchMinutes || interval > MaxWatchMinutes {
+		return fmt.Errorf("%w: interval_minutes must be between %d and %d", ErrInvalid, MinWatchMinutes, MaxWatchMinutes)
+	}
+
+	errorEmails := make([]string, 0, len(req.ErrorEmails))
+	for _, email := range req.ErrorEmails {
+		address, err := mail.ParseAddress(strings.TrimSpace(email))
+		if err != nil {
+			return fmt.Errorf("%w: invalid error email %q", ErrInvalid, email)
+		}
+		errorEmails = append(errorEmails, address.Address)
+	}
+
+	watcher.Name = name
+	watcher.DestinationID = req.DestinationID
+	watcher.MappingID = req.MappingID
+	watcher.Pattern = pattern
+	watcher.ArchivePath = archivePath
+	watcher.ErrorEmails = errorEmails
+	watcher.IntervalMinutes = interval
+	if req.Active != nil {
+		watcher.Active = *req.Active
+	}
+	watcher.NextPollAt = nil
+	if watcher.Active {
+		next := s.now()
+		watcher.NextPollAt = &next
+	}
+	return nil
+}
+
+// DeleteWatcher removes a watcher; its import log is kept
+func (s *ImportService) DeleteWatcher(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteWatcher(ctx, orgID, id)
+}
+
+// ListImportFiles returns the import log, latest first
+func (s *ImportService) ListImportFiles(ctx context.Context, filter types.ImportFileFilter) ([]types.ImportFile, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultImportFileLimit
+	}
+	if filter.Limit > MaxImportFileLimit {
+		filter.Limit = MaxImportFileLimit
+	}
+	return s.repo.ListImportFiles(ctx, filter)
+}
+
+// GetImportFile returns a logged file with its row errors
+func (s *ImportService) GetImportFile(ctx context.Context, orgID, id uuid.UUID) (*types.ImportFile, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindImportFile(ctx, orgID, id)
+}
+
+// PollNow polls a watcher immediately, outside its interval. A poll that
+// cannot reach the files reports why, with the files it imported before.
+func (s *ImportService) PollNow(ctx context.Context, orgID, id uuid.UUID) (*types.WatchPoll, error) {
+	if err := s.authService.CheckPermission(ctx, "imports:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	watcher, err := s.repo.FindWatcher(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.poll(ctx, *watcher)
+	poll := &types.WatchPoll{OK: err == nil, Files: files}
+	if err != nil {
+		poll.Error = err.Error()
+	}
+	if poll.Files == nil {
+		poll.Files = []types.ImportFile{}
+	}
+	return poll, nil
+}
+
+// RunDueWatchers polls the watchers whose time has come. Each is moved to
+// its next poll first, so a watcher that fails waits for its interval
+// rather than retrying in a loop.
+func (s *ImportService) RunDueWatchers(ctx context.Context) (*types.WatchRun, error) {
+	run := &types.WatchRun{}
+	for {
+		now := s.now()
+		watchers, err := s.repo.ListDueWatchers(ctx, now, watchBatchSize)
+		if err != nil {
+			return run, err
+		}
+		moved := 0
+		for _, watcher := range watchers {
+			next := now.Add(time.Duration(watcher.IntervalMinutes) * time.Minute)
+			if err := s.repo.SetNextPoll(ctx, watcher.OrganizationID, watcher.ID, next); err != nil {
+				s.logger.Warn("Failed to reschedule import watcher", "watcher_id", watcher.ID, "error", err)
+				continue
+			}
+			moved++
+			files, err := s.poll(ctx, watcher)
+			run.Polled++
+			run.Files += len(files)
+			if err != nil {
+				run.Failed++
+			}
+		}
+		// Watchers that failed to move are still due; stop rather than
+		// fetching them again
+		if len(watchers) < watchBatchSize || moved == 0 {
+			return run, nil
+		}
+	}
+}
+
+// poll imports the watcher's new files and records the outcome on the
+// watcher. The first of a run of failed polls is alerted; the next success
+// resets it.
+func (s *ImportService) poll(ctx context.Context, watcher types.Watcher) ([]types.ImportFile, error) {
+	started := s.now()
+	files, pollErr := s.importFiles(ctx, watcher)
+
+	status := types.TransferSucceeded
+	watcher.LastPollAt = &started
+	watcher.LastError = ""
+	if pollErr != nil {
+		status = types.TransferFailed
+		watcher.LastError = pollErr.Error()
+		watcher.ConsecutiveFailures++
+		s.logger.Warn("Import watcher poll failed", "watcher_id", watcher.ID, "error", pollErr)
+		if watcher.ConsecutiveFailures == 1 {
+			s.alertPoll(ctx, watcher)
+		}
+	} else {
+		watcher.ConsecutiveFailures = 0
+	}
+	watcher.LastStatus = &status
+
+	if err := s.repo.RecordPoll(ctx, watcher); err != nil {
+		s.logger.Warn("Failed to record import poll", "watcher_id", watcher.ID, "error", err)
+	}
+	return files, pollErr
+}
+
+// importFiles lists the watcher's path and imports the files matching its
+// pattern, oldest first
+func (s *ImportService) importFiles(ctx context.Context, watcher types.Watcher) ([]types.ImportFile, error) {
+	destination, err := s.exports.repo.FindDestination(ctx, watcher.OrganizationID, watcher.DestinationID)
+	if err != nil {
+		return nil, err
+	}
+	if !destination.Active {
+		return nil, fmt.Errorf("destination %q is inactive", destination.Name)
+	}
+	mapping, err := s.repo.FindMapping(ctx, watcher.OrganizationID, watcher.MappingID)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.exports.openCredentials(*destination)
+	if err != nil {
+		return nil, err
+	}
+	transport := s.exports.transports[destination.Kind]
+
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+	remote, err := transport.List(ctx, *destination, creds)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(remote, func(i, j int) bool {
+		if !remote[i].ModTime.Equal(remote[j].ModTime) {
+			return remote[i].ModTime.Before(remote[j].ModTime)
+		}
+		return remote[i].Name < remote[j].Name
+	})
+
+	source := importSource{destination: *destination, creds: creds, transport: transport}
+	imported := []types.ImportFile{}
+	for _, file := range remote {
+		if matched, _ := path.Match(watcher.Pattern, file.Name); !matched {
+			continue
+		}
+		if !file.ModTime.IsZero() && s.now().Sub(file.ModTime) < fileSettleTime {
+			continue
+		}
+		if len(imported) == maxFilesPerPoll {
+			break
+		}
+		logged, err := s.importFile(ctx, watcher, *mapping, source, file)
+		if err != nil {
+			return imported, err
+		}
+		if logged != nil {
+			imported = append(imported, *logged)
+		}
+	}
+	return imported, nil
+}
+
+// importSource is where a watcher's files are fetched from
+type importSource struct {
+	destination types.Destination
+	creds       types.Credentials
+	transport   Transport
+}
+
+// importFile downloads a file, imports its rows, archives it, logs it and
+// reports its errors. A file the watcher already imported, but could not
+// archive, is only archived; it returns nil. The returned error is about
+// reaching or logging the file; a file that cannot be imported is logged
+// with the failed status.
+func (s *ImportService) importFile(ctx context.Context, watcher types.Watcher, mapping types.Mapping, source importSource, file types.RemoteFile) (*types.ImportFile, error) {
+	record := types.ImportFile{
+		ID:             uuid.New(),
+		OrganizationID: watcher.OrganizationID,
+		WatcherID:      watcher.ID,
+		MappingID:      mapping.ID,
+		Entity:         mapping.Entity,
+		RemotePath:     file.Path,
+		Size:           file.Size,
+		Status:         types.ImportFileSucceeded,
+		StartedAt:      s.now(),
+	}
+
+	if file.Size > MaxImportFileSize {
+		record.Status = types.ImportFileFailed
+		record.Error = fmt.Sprintf("file is larger than the %d MB limit", MaxImportFileSize>>20)
+	} else {
+		tmp, err := os.CreateTemp("", "import-*.csv")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create import file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		hash := sha256.New()
+		size, err := source.transport.Fetch(ctx, source.destination, source.creds, file.Path, io.MultiWriter(tmp, hash))
+		if err != nil {
+			return nil, err
+		}
+		record.Size = size
+		record.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+		previous, err := s.repo.FindImportedFile(ctx, watcher.ID, record.SHA256)
+		if err == nil {
+			s.logger.Info("Import file already imported; archiving it", "watcher_id", watcher.ID,
+				"remote_path", file.Path, "import_file_id", previous.ID)
+			s.archive(ctx, watcher, source, file, record.StartedAt)
+			return nil, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read import file: %w", err)
+		}
+		if err := s.load(ctx, mapping, tmp, &record); err != nil {
+			record.Status = types.ImportFileFailed
+			record.Error = err.Error()
+		} else if record.Failed > 0 {
+			record.Status = types.ImportFilePartial
+		}
+	}
+
+	record.ArchivedPath = s.archive(ctx, watcher, source, file, record.StartedAt)
+	record.FinishedAt = s.now()
+	logged, err := s.repo.CreateImportFile(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Import file processed", "watcher_id", watcher.ID, "remote_path", file.Path, "status", logged.Status,
+		"rows", logged.Rows, "created", logged.Created, "updated", logged.Updated, "failed", logged.Failed)
+
+	s.publishEvent(ctx, EventFileImported, map[string]interface{}{
+		"organization_id": watcher.OrganizationID,
+		"watcher_id":      watcher.ID,
+		"import_file_id":  logged.ID,
+		"entity":          logged.Entity,
+		"status":          logged.Status,
+		"rows":            logged.Rows,
+		"created":         logged.Created,
+		"updated":         logged.Updated,
+		"failed":          logged.Failed,
+	})
+	if logged.Status != types.ImportFileSucceeded {
+		s.reportErrors(ctx, watcher, *logged)
+	}
+	return logged, nil
+}
+
+// archive moves a processed file to the watcher's archive directory under
+// a timestamped name and returns where it went, or "" when it could not be
+// moved; it is then archived by a later poll
+func (s *ImportService) archive(ctx context.Context, watcher types.Watcher, source importSource, file types.RemoteFile, started time.Time) string {
+	name := started.UTC().Format("20060102T150405") + "_" + file.Name
+	target := path.Join(source.destination.Path, watcher.ArchivePath, name)
+	if source.destination.Kind == types.DestinationS3 {
+		target = strings.TrimPrefix(target, "/")
+	}
+	if err := source.transport.Move(ctx, source.destination, source.creds, file.Path, target); err != nil {
+		s.logger.Warn("Failed to archive import file", "watcher_id", watcher.ID, "remote_path", file.Path, "error", err)
+		return ""
+	}
+	return target
+}
+
+// load imports the rows of a CSV file through the mapping, counting them
+// on record and collecting their errors. The returned error stops the
+// import: the file cannot be read, or a row could not be written for a
+// reason other than its values.
+func (s *ImportService) load(ctx context.Context, mapping types.Mapping, r io.Reader, record *types.ImportFile) error {
+	load, ok := repository.FindEntityLoad(mapping.Entity)
+	if !ok {
+		return fmt.Errorf("%s can no longer be imported", mapping.Entity)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("file is empty")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the header: %w", err)
+	}
+
+	columns := make(map[string]string, len(mapping.Columns))
+	for column, field := range mapping.Columns {
+		columns[strings.ToLower(column)] = field
+	}
+	fields := make([]string, len(header))
+	found := make(map[string]bool, len(header))
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		if field, ok := columns[strings.ToLower(strings.TrimSpace(column))]; ok {
+			fields[i] = field
+			found[field] = true
+		}
+	}
+	if len(found) == 0 {
+		return errors.New("none of the mapping's columns are in the header")
+	}
+	if mapping.MatchField != "" && !found[mapping.MatchField] {
+		return fmt.Errorf("the column mapped to %s, which rows are matched on, is missing", mapping.MatchField)
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			record.Rows++
+			record.Failed++
+			addRowError(record, types.RowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		values, rowErrs := rowValues(load, fields, row, line)
+		if len(values) == 0 && len(rowErrs) == 0 {
+			continue
+		}
+		record.Rows++
+		if len(rowErrs) > 0 {
+			record.Failed++
+			for _, rowErr := range rowErrs {
+				addRowError(record, rowErr)
+			}
+			continue
+		}
+
+		created, err := s.repo.LoadEntity(ctx, mapping.Entity, record.OrganizationID, mapping.MatchField, values)
+		var fieldErr *repository.FieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			record.Failed++
+			addRowError(record, types.RowError{Line: line, Field: fieldErr.Field, Message: fieldErr.Message})
+		case err != nil:
+			return err
+		case created:
+			record.Created++
+		default:
+			record.Updated++
+		}
+	}
+}
+
+// rowValues reads the mapped, non-empty values of a row, checked against
+// their fields' kinds
+func rowValues(load *repository.EntityLoad, fields, row []string, line int) (map[string]string, []types.RowError) {
+	values := make(map[string]string, len(fields))
+	var rowErrs []types.RowError
+	for i, value := range row {
+		if i >= len(fields) || fields[i] == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		field, _ := load.Field(fields[i])
+		switch field.Kind {
+		case types.ImportFieldBool:
+			switch strings.ToLower(value) {
+			case "true", "yes", "y", "1", "x":
+				value = "true"
+			case "false", "no", "n", "0":
+				value = "false"
+			default:
+				rowErrs = append(rowErrs, types.RowError{Line: line, Field: field.Name, Message: fmt.Sprintf("%q is not yes or no", value)})
+				continue
+			}
+		case types.ImportFieldNumber:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				rowErrs = append(rowErrs, types.RowError{Line: line, Field: field.Name, Message: fmt.Sprintf("%q is not a number", value)})
+				continue
+			}
+			value = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		values[field.Name] = value
+	}
+	return values, rowErrs
+}
+
+// addRowError logs a row error, up to maxRowErrors per file
+func addRowError(record *types.ImportFile, rowErr types.RowError) {
+	if len(record.Errors) < maxRowErrors {
+		record.Errors = append(record.Errors, rowErr)
+	}
+}
+
+// reportErrors emails the watcher's error addresses the outcome of a file
+// that failed or had row errors
+func (s *ImportService) reportErrors(ctx context.Context, watcher types.Watcher, file types.ImportFile) {
+	if s.exports.mailer == nil {
+		return
+	}
+
+	name := path.Base(file.RemotePath)
+	var subject, body string
+	if file.Status == types.ImportFileFailed {
+		subject = fmt.Sprintf("Import of %s failed", name)
+		body = fmt.Sprintf("The import watcher %q could not import %s at %s:\n\n%s\n\n",
+			watcher.Name, file.RemotePath, file.StartedAt.UTC().Format(time.RFC1123), file.Error)
+	} else {
+		subject = fmt.Sprintf("Import of %s had %d failed rows", name, file.Failed)
+		body = fmt.Sprintf("The import watcher %q imported %s at %s.\n\n",
+			watcher.Name, file.RemotePath, file.StartedAt.UTC().Format(time.RFC1123))
+	}
+	body += fmt.Sprintf("Rows: %d\nCreated: %d\nUpdated: %d\nFailed: %d\n", file.Rows, file.Created, file.Updated, file.Failed)
+
+	if len(file.Errors) > 0 {
+		body += "\nRow errors:\n"
+		for i, rowErr := range file.Errors {
+			if i == mailedRowErrors {
+				body += fmt.Sprintf("...and %d more; see the import log.\n", len(file.Errors)-mailedRowErrors)
+				break
+			}
+			if rowErr.Field != "" {
+				body += fmt.Sprintf("Line %d, %s: %s\n", rowErr.Line, rowErr.Field, rowErr.Message)
+			} else {
+				body += fmt.Sprintf("Line %d: %s\n", rowErr.Line, rowErr.Message)
+			}
+		}
+	}
+	if file.ArchivedPath != "" {
+		body += fmt.Sprintf("\nThe file was moved to %s.\n", file.ArchivedPath)
+	}
+
+	for _, to := range watcher.ErrorEmails {
+		if err := s.exports.mailer.Send(metering.WithOrganization(ctx, watcher.OrganizationID), to, subject, body); err != nil {
+			s.logger.Warn("Failed to send import error report", "watcher_id", watcher.ID, "to", to, "error", err)
+		}
+	}
+}
+
+// alertPoll emails the watcher's error addresses and publishes that it
+// cannot reach its files
+func (s *ImportService) alertPoll(ctx context.Context, watcher types.Watcher) {
+	s.publishEvent(ctx, EventWatchFailed, map[string]interface{}{
+		"organization_id": watcher.OrganizationID,
+		"watcher_id":      watcher.ID,
+		"error":           watcher.LastError,
+	})
+	if s.exports.mailer == nil {
+		return
+	}
+
+	subject := fmt.Sprintf("Import watcher %q cannot reach its files", watcher.Name)
+	body := fmt.Sprintf("The import watcher %q failed to poll for files:\n\n%s\n\n"+
+		"It keeps trying every %d minutes. You will not be emailed again until it has succeeded.\n",
+		watcher.Name, watcher.LastError, watcher.IntervalMinutes)
+	for _, to := range watcher.ErrorEmails {
+		if err := s.exports.mailer.Send(metering.WithOrganization(ctx, watcher.OrganizationID), to, subject, body); err != nil {
+			s.logger.Warn("Failed to send import watcher alert", "watcher_id", watcher.ID, "to", to, "error", err)
+		}
+	}
+}
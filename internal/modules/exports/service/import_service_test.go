@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeImportRepo struct {
+	mappings map[uuid.UUID]types.Mapping
+	watchers map[uuid.UUID]types.Watcher
+	files    []types.ImportFile
+	// records are the loaded records by the value of the match field
+	records map[string]map[string]string
+}
+
+func newFakeImportRepo() *fakeImportRepo {
+	return &fakeImportRepo{
+		mappings: make(map[uuid.UUID]types.Mapping),
+		watchers: make(map[uuid.UUID]types.Watcher),
+		records:  make(map[string]map[string]string),
+	}
+}
+
+func (f *fakeImportRepo) ListMappings(ctx context.Context, orgID uuid.UUID) ([]types.Mapping, error) {
+	var mappings []types.Mapping
+	for _, m := range f.mappings {
+		if m.OrganizationID == orgID {
+			mappings = append(mappings, m)
+		}
+	}
+	return mappings, nil
+}
+
+func (f *fakeImportRepo) FindMapping(ctx context.Context, orgID, id uuid.UUID) (*types.Mapping, error) {
+	m, ok := f.mappings[id]
+	if !ok || m.OrganizationID != orgID {
+		return nil, repository.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (f *fakeImportRepo) CreateMapping(ctx context.Context, mapping types.Mapping) (*types.Mapping, error) {
+	f.mappings[mapping.ID] = mapping
+	return &mapping, nil
+}
+
+func (f *fakeImportRepo) UpdateMapping(ctx context.Context, mapping types.Mapping) (*types.Mapping, error) {
+	f.mappings[mapping.ID] = mapping
+	return &mapping, nil
+}
+
+func (f *fakeImportRepo) DeleteMapping(ctx context.Context, orgID, id uuid.UUID) error {
+	for _, w := range f.watchers {
+		if w.MappingID == id {
+			return repository.ErrInUse
+		}
+	}
+	delete(f.mappings, id)
+	return nil
+}
+
+func (f *fakeImportRepo) ListWatchers(ctx context.Context, orgID uuid.UUID) ([]types.Watcher, error) {
+	var watchers []types.Watcher
+	for _, w := range f.watchers {
+		if w.OrganizationID == orgID {
+			watchers = append(watchers, w)
+		}
+	}
+	return watchers, nil
+}
+
+func (f *fakeImportRepo) FindWatcher(ctx context.Context, orgID, id uuid.UUID) (*types.Watcher, error) {
+	w, ok := f.watchers[id]
+	if !ok || w.OrganizationID != orgID {
+		return nil, repository.ErrNotFound
+	}
+	return &w, nil
+}
+
+func (f *fakeImportRepo) CreateWatcher(ctx context.Context, watcher types.Watcher) (*types.Watcher, error) {
+	f.watchers[watcher.ID] = watcher
+	return &watcher, nil
+}
+
+func (f *fakeImportRepo) UpdateWatcher(ctx context.Context, watcher types.Watcher) (*types.Watcher, error) {
+	f.watchers[watcher.ID] = watcher
+	return &watcher, nil
+}
+
+func (f *fakeImportRepo) DeleteWatcher(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.watchers, id)
+	return nil
+}
+
+func (f *fakeImportRepo) ListDueWatchers(ctx context.Context, now time.Time, limit int) ([]types.Watcher, error) {
+	var due []types.Watcher
+	for _, w := range f.watchers {
+		if w.Active && w.NextPollAt != nil && !w.NextPollAt.After(now) {
+			due = append(due, w)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeImportRepo) SetNextPoll(ctx context.Context, orgID, id uuid.UUID, next time.Time) error {
+	w := f.watchers[id]
+	w.NextPollAt = &next
+	f.watchers[id] = w
+	return nil
+}
+
+func (f *fakeImportRepo) RecordPoll(ctx context.Context, watcher types.Watcher) error {
+	w := f.watchers[watcher.ID]
+	w.LastPollAt = watcher.LastPollAt
+	w.LastStatus = watcher.LastStatus
+	w.LastError = watcher.LastError
+	w.ConsecutiveFailures = watcher.ConsecutiveFailures
+	f.watchers[watcher.ID] = w
+	return nil
+}
+
+func (f *fakeImportRepo) FindImportedFile(ctx context.Context, watcherID uuid.UUID, sha256 string) (*types.ImportFile, error) {
+	for _, file := range f.files {
+		if file.WatcherID == watcherID && file.SHA256 == sha256 && file.Status != types.ImportFileFailed {
+			return &file, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeImportRepo) CreateImportFile(ctx context.Context, file types.ImportFile) (*types.ImportFile, error) {
+	f.files = append(f.files, file)
+	return &file, nil
+}
+
+func (f *fakeImportRepo) FindImportFile(ctx context.Context, orgID, id uuid.UUID) (*types.ImportFile, error) {
+	for _, file := range f.files {
+		if file.ID == id && file.OrganizationID == orgID {
+			return &file, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeImportRepo) ListImportFiles(ctx context.Context, filter types.ImportFileFilter) ([]types.ImportFile, error) {
+	return f.files, nil
+}
+
+func (f *fakeImportRepo) LoadEntity(ctx context.Context, entity string, orgID uuid.UUID, matchField string, values map[string]string) (bool, error) {
+	key := values[matchField]
+	if existing, ok := f.records[key]; ok && key != "" {
+		for field, value := range values {
+			existing[field] = value
+		}
+		return false, nil
+	}
+	if values["name"] == "" {
+		return false, &repository.FieldError{Field: "name", Message: "is required"}
+	}
+	f.records[key] = values
+	return true, nil
+}
+
+func newTestImportService(repo *fakeImportRepo, exportRepo *fakeExportRepo, transport *fakeTransport, clock *time.Time) *ImportService {
+	return NewImportService(repo, newTestService(exportRepo, transport, clock), allowAll{}, nil, nil)
+}
+
+func createTestWatcher(t *testing.T, svc *ImportService, orgID uuid.UUID) *types.Watcher {
+	ctx := context.Background()
+	destination, err := svc.exports.CreateDestination(ctx, orgID, uuid.New(), types.DestinationRequest{
+		Name: "Acme SFTP", Kind: types.DestinationSFTP, Host: "sftp.example.com", Username: "erp",
+		HostKey: testHostKey, Path: "/inbox", Credentials: &types.Credentials{Password: "s3cret"},
+	})
+	require.NoError(t, err)
+	mapping, err := svc.CreateMapping(ctx, orgID, uuid.New(), types.MappingRequest{
+		Name: "Customer list", Entity: "contacts", MatchField: "email",
+		Columns: map[string]string{"Name": "name", "E-mail": "email", "Customer": "is_customer"},
+	})
+	require.NoError(t, err)
+	watcher, err := svc.CreateWatcher(ctx, orgID, uuid.New(), types.WatcherRequest{
+		Name: "Customer drop", DestinationID: destination.ID, MappingID: mapping.ID,
+		ErrorEmails: []string{"Ops <ops@example.com>"},
+	})
+	require.NoError(t, err)
+	return watcher
+}
+
+func TestMappingValidation(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	svc := newTestImportService(newFakeImportRepo(), newFakeExportRepo(), &fakeTransport{}, &clock)
+	orgID := uuid.New()
+
+	for name, req := range map[string]types.MappingRequest{
+		"unknown entity":      {Name: "m", Entity: "planets", Columns: map[string]string{"Name": "name"}},
+		"no columns":          {Name: "m", Entity: "contacts"},
+		"unknown field":       {Name: "m", Entity: "contacts", Columns: map[string]string{"Name": "nickname"}},
+		"field mapped twice":  {Name: "m", Entity: "contacts", Columns: map[string]string{"Name": "name", "Full name": "name"}},
+		"column mapped twice": {Name: "m", Entity: "contacts", Columns: map[string]string{"Name": "name", "name": "email"}},
+		"unmatchable field":   {Name: "m", Entity: "contacts", Columns: map[string]string{"Name": "name"}, MatchField: "name"},
+		"unmapped match":      {Name: "m", Entity: "contacts", Columns: map[string]string{"Name": "name"}, MatchField: "email"},
+	} {
+		_, err := svc.CreateMapping(ctx, orgID, uuid.New(), req)
+		assert.ErrorIs(t, err, ErrInvalid, name)
+	}
+
+	mapping, err := svc.CreateMapping(ctx, orgID, uuid.New(), types.MappingRequest{
+		Name: " Products ", Entity: "products", MatchField: "default_code",
+		Columns: map[string]string{" SKU ": "default_code", "Title": "name"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Products", mapping.Name)
+	assert.Equal(t, map[string]string{"SKU": "default_code", "Title": "name"}, mapping.Columns)
+}
+
+func TestWatcherImportsArchivesAndReportsRowErrors(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	repo := newFakeImportRepo()
+	transport := &fakeTransport{}
+	svc := newTestImportService(repo, newFakeExportRepo(), transport, &clock)
+	mailer := &fakeMailer{}
+	svc.exports.SetMailer(mailer)
+	orgID := uuid.New()
+	watcher := createTestWatcher(t, svc, orgID)
+	assert.Equal(t, "*.csv", watcher.Pattern)
+	assert.Equal(t, []string{"ops@example.com"}, watcher.ErrorEmails)
+
+	repo.records["globex@example.com"] = map[string]string{"name": "Globex", "email": "globex@example.com"}
+	transport.put(types.RemoteFile{Path: "/inbox/customers.csv", Name: "customers.csv", ModTime: clock.Add(-time.Hour)},
+		"\ufeffname,e-mail,Customer,Notes\n"+
+			"Acme,acme@example.com,yes,new\n"+
+			",globex@example.com,x,updated\n"+
+			",nobody@example.com,,no name\n"+
+			"Initech,initech@example.com,maybe,bad flag\n")
+	transport.put(types.RemoteFile{Path: "/inbox/readme.txt", Name: "readme.txt", ModTime: clock.Add(-time.Hour)}, "not a CSV")
+	transport.put(types.RemoteFile{Path: "/inbox/uploading.csv", Name: "uploading.csv", ModTime: clock.Add(-10 * time.Second)}, "name\n")
+
+	run, err := svc.RunDueWatchers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &types.WatchRun{Polled: 1, Files: 1}, run)
+
+	require.Len(t, repo.files, 1)
+	file := repo.files[0]
+	assert.Equal(t, types.ImportFilePartial, file.Status)
+	assert.Equal(t, 4, file.Rows)
+	assert.Equal(t, 1, file.Created)
+	assert.Equal(t, 1, file.Updated)
+	assert.Equal(t, 2, file.Failed)
+	assert.Equal(t, []types.RowError{
+		{Line: 4, Field: "name", Message: "is required"},
+		{Line: 5, Field: "is_customer", Message: `"maybe" is not yes or no`},
+	}, file.Errors)
+	assert.Equal(t, "/inbox/archive/20250310T080000_customers.csv", file.ArchivedPath)
+	assert.Len(t, file.SHA256, 64)
+	assert.Equal(t, "true", repo.records["acme@example.com"]["is_customer"])
+	assert.Equal(t, "true", repo.records["globex@example.com"]["is_customer"])
+
+	assert.Equal(t, map[string]string{"/inbox/customers.csv": file.ArchivedPath}, transport.moved)
+	assert.Contains(t, transport.files, "/inbox/readme.txt")
+	assert.Contains(t, transport.files, "/inbox/uploading.csv")
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "ops@example.com", mailer.sent[0].to)
+	assert.Equal(t, "Import of customers.csv had 2 failed rows", mailer.sent[0].subject)
+	assert.Contains(t, mailer.sent[0].body, "Line 4, name: is required")
+	assert.Contains(t, mailer.sent[0].body, "moved to /inbox/archive/20250310T080000_customers.csv")
+
+	stored := repo.watchers[watcher.ID]
+	require.NotNil(t, stored.LastStatus)
+	assert.Equal(t, types.TransferSucceeded, *stored.LastStatus)
+	assert.Equal(t, clock.Add(15*time.Minute), *stored.NextPollAt)
+
+	// The settled upload is imported on request; without a match column
+	// the whole file fails
+	clock = clock.Add(5 * time.Minute)
+	poll, err := svc.PollNow(ctx, orgID, watcher.ID)
+	require.NoError(t, err)
+	assert.True(t, poll.OK)
+	require.Len(t, poll.Files, 1)
+	assert.Equal(t, types.ImportFileFailed, poll.Files[0].Status)
+	assert.Contains(t, poll.Files[0].Error, "email")
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, "Import of uploading.csv failed", mailer.sent[1].subject)
+}
+
+func TestWatcherSkipsFilesAlreadyImported(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	repo := newFakeImportRepo()
+	transport := &fakeTransport{}
+	svc := newTestImportService(repo, newFakeExportRepo(), transport, &clock)
+	orgID := uuid.New()
+	watcher := createTestWatcher(t, svc, orgID)
+
+	content := "Name,E-mail\nAcme,acme@example.com\n"
+	transport.put(types.RemoteFile{Path: "/inbox/a.csv", Name: "a.csv", ModTime: clock.Add(-time.Hour)}, content)
+	_, err := svc.PollNow(ctx, orgID, watcher.ID)
+	require.NoError(t, err)
+	require.Len(t, repo.files, 1)
+
+	// The same file, left behind by an archive that failed, is only
+	// archived
+	transport.put(types.RemoteFile{Path: "/inbox/a.csv", Name: "a.csv", ModTime: clock.Add(-time.Hour)}, content)
+	poll, err := svc.PollNow(ctx, orgID, watcher.ID)
+	require.NoError(t, err)
+	assert.Empty(t, poll.Files)
+	assert.Len(t, repo.files, 1)
+	assert.NotContains(t, transport.files, "/inbox/a.csv")
+}
+
+func TestWatcherPollFailureAlertsOnce(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	repo := newFakeImportRepo()
+	transport := &fakeTransport{}
+	svc := newTestImportService(repo, newFakeExportRepo(), transport, &clock)
+	mailer := &fakeMailer{}
+	svc.exports.SetMailer(mailer)
+	orgID := uuid.New()
+	watcher := createTestWatcher(t, svc, orgID)
+
+	transport.err = errors.New("connection refused")
+	for i := 0; i < 2; i++ {
+		poll, err := svc.PollNow(ctx, orgID, watcher.ID)
+		require.NoError(t, err)
+		assert.False(t, poll.OK)
+		assert.Equal(t, "connection refused", poll.Error)
+		assert.Empty(t, poll.Files)
+	}
+	assert.Equal(t, 2, repo.watchers[watcher.ID].ConsecutiveFailures)
+	require.Len(t, mailer.sent, 1)
+	assert.True(t, strings.HasPrefix(mailer.sent[0].subject, `Import watcher "Customer drop"`))
+
+	transport.err = nil
+	_, err := svc.PollNow(ctx, orgID, watcher.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, repo.watchers[watcher.ID].ConsecutiveFailures)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+)
+
+// nextRun returns the first time after after that the schedule runs. Times
+// are wall-clock times in the schedule's timezone, so a 02:00 export stays
+// at 02:00 across daylight saving changes.
+func nextRun(schedule types.Schedule, after time.Time) time.Time {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	clock, err := time.Parse("15:04", schedule.TimeOfDay)
+	if err != nil {
+		clock = time.Time{}
+	}
+	local := after.In(loc)
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+
+	switch schedule.Frequency {
+	case types.FrequencyMonthly:
+		day := 1
+		if schedule.DayOfMonth != nil {
+			day = *schedule.DayOfMonth
+		}
+		next := at(local.Year(), local.Month(), day)
+		if !next.After(after) {
+			next = at(local.Year(), local.Month()+1, day)
+		}
+		return next
+	default:
+		for i := 0; ; i++ {
+			next := at(local.Year(), local.Month(), local.Day()+i)
+			if !next.After(after) {
+				continue
+			}
+			if schedule.Frequency == types.FrequencyWeekly && schedule.Weekday != nil && int(next.Weekday()) != *schedule.Weekday {
+				continue
+			}
+			return next
+		}
+	}
+}
+
+// remoteName returns the filename of a run started at started, with the
+// placeholders replaced in the schedule's timezone
+func remoteName(schedule types.Schedule, started time.Time) string {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := started.In(loc)
+	return strings.NewReplacer(
+		"{date}", local.Format("2006-01-02"),
+		"{timestamp}", local.Format("20060102T150405"),
+	).Replace(schedule.Filename)
+}
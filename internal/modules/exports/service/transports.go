@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/exports/types"
+	"github.com/KevTiv/alieze-erp/pkg/sftp"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialTimeout bounds connecting and authenticating to SFTP servers
+const dialTimeout = 30 * time.Second
+
+// Transport delivers export files to one kind of destination, and picks up
+// the files import watchers find there
+type Transport interface {
+	// Send writes the size bytes of r as name in the destination's path and
+	// returns where the file was written
+	Send(ctx context.Context, destination types.Destination, creds types.Credentials, name string, r io.Reader, size int64) (string, error)
+	// Check connects to the destination and ensures its path exists
+	Check(ctx context.Context, destination types.Destination, creds types.Credentials) error
+	// List returns the files directly in the destination's path
+	List(ctx context.Context, destination types.Destination, creds types.Credentials) ([]types.RemoteFile, error)
+	// Fetch writes the content of the file at remotePath to w and returns
+	// the bytes written
+	Fetch(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath string, w io.Writer) (int64, error)
+	// Move moves the file at remotePath to target, creating its directory
+	// when missing
+	Move(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath, target string) error
+}
+
+// SFTPTransport uploads files to SFTP servers. Files are written under a
+// temporary name and renamed once complete, so that customers' jobs never
+// pick up a partial file.
+type SFTPTransport struct{}
+
+func (t *SFTPTransport) dial(ctx context.Context, destination types.Destination, creds types.Credentials) (*sftp.Client, error) {
+	hostKey, err := hostKeyCallback(destination.HostKey)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := sshAuth(creds)
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User:            destination.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         dialTimeout,
+	}
+	addr := net.JoinHostPort(destination.Host, strconv.Itoa(destination.Port))
+	client, err := sftp.Dial(ctx, addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return client, nil
+}
+
+// Send uploads the file to the server's path
+func (t *SFTPTransport) Send(ctx context.Context, destination types.Destination, creds types.Credentials, name string, r io.Reader, size int64) (string, error) {
+	client, err := t.dial(ctx, destination, creds)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	target := path.Join(destination.Path, name)
+	partial := target + ".part"
+	written, err := client.Create(partial, r)
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d of %d bytes", written, size)
+	}
+	if err != nil {
+		client.Remove(partial)
+		return "", fmt.Errorf("failed to upload %s: %w", partial, err)
+	}
+	// SFTP version 3 servers do not rename over existing files
+	if err := client.Remove(target); err != nil && !errors.Is(err, sftp.ErrNotExist) {
+		return "", fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	if err := client.Rename(partial, target); err != nil {
+		return "", fmt.Errorf("failed to rename %s: %w", partial, err)
+	}
+	return target, nil
+}
+
+// Check signs in and ensures the path is a directory
+func (t *SFTPTransport) Check(ctx context.Context, destination types.Destination, creds types.Credentials) error {
+	client, err := t.dial(ctx, destination, creds)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	info, err := client.Stat(destination.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", destination.Path, err)
+	}
+	if !info.IsDir {
+		return fmt.Errorf("%s is not a directory", destination.Path)
+	}
+	return nil
+}
+
+// List reads the server's path, leaving out directories
+func (t *SFTPTransport) List(ctx context.Context, destination types.Destination, creds types.Credentials) ([]types.RemoteFile, error) {
+	client, err := t.dial(ctx, destination, creds)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	entries, err := client.ReadDir(destination.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", destination.Path, err)
+	}
+	files := make([]types.RemoteFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		files = append(files, types.RemoteFile{
+			Path:    path.Join(destination.Path, entry.Name),
+			Name:    entry.Name,
+			Size:    entry.Size,
+			ModTime: entry.ModTime,
+		})
+	}
+	return files, nil
+}
+
+// Fetch downloads the file at remotePath
+func (t *SFTPTransport) Fetch(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath string, w io.Writer) (int64, error) {
+	client, err := t.dial(ctx, destination, creds)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	n, err := client.Read(remotePath, w)
+	if err != nil {
+		return n, fmt.Errorf("failed to download %s: %w", remotePath, err)
+	}
+	return n, nil
+}
+
+// Move renames the file at remotePath to target
+func (t *SFTPTransport) Move(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath, target string) error {
+	client, err := t.dial(ctx, destination, creds)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	dir := path.Dir(target)
+	if _, err := client.Stat(dir); errors.Is(err, sftp.ErrNotExist) {
+		if err := client.Mkdir(dir); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	if err := client.Rename(remotePath, target); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", remotePath, target, err)
+	}
+	return nil
+}
+
+// hostKeyCallback accepts only the pinned host key, given as its SHA256
+// fingerprint, a public key line or a known_hosts line
+func hostKeyCallback(pinned string) (ssh.HostKeyCallback, error) {
+	pinned = strings.TrimSpace(pinned)
+	if strings.HasPrefix(pinned, "SHA256:") {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != pinned {
+				return fmt.Errorf("host key %s does not match the pinned key", ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}, nil
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pinned))
+	if err != nil {
+		_, _, key, _, _, err = ssh.ParseKnownHosts([]byte(pinned))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: host key must be a SHA256 fingerprint or a public key", ErrInvalid)
+	}
+	return ssh.FixedHostKey(key), nil
+}
+
+// sshAuth returns the authentication methods of the credentials
+func sshAuth(creds types.Credentials) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if creds.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if creds.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(creds.PrivateKey), []byte(creds.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(creds.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: private key cannot be read: %v", ErrInvalid, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if creds.Password != "" {
+		password := creds.Password
+		methods = append(methods, ssh.Password(password),
+			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}))
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("%w: a password or private key is required", ErrInvalid)
+	}
+	return methods, nil
+}
+
+// S3Transport uploads files to S3 buckets and S3-compatible services
+type S3Transport struct{}
+
+func (t *S3Transport) open(destination types.Destination, creds types.Credentials) (*storage.S3Storage, error) {
+	return storage.NewS3Storage(&storage.S3Config{
+		Region:         destination.Region,
+		Bucket:         destination.Bucket,
+		Endpoint:       destination.Endpoint,
+		AccessKey:      creds.AccessKey,
+		SecretKey:      creds.SecretKey,
+		UseSSL:         true,
+		ForcePathStyle: destination.Endpoint != "",
+	})
+}
+
+// Send uploads the file under the destination's key prefix
+func (t *S3Transport) Send(ctx context.Context, destination types.Destination, creds types.Credentials, name string, r io.Reader, size int64) (string, error) {
+	bucket, err := t.open(destination, creds)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimPrefix(path.Join(destination.Path, name), "/")
+	_, err = bucket.Upload(ctx, storage.UploadOptions{
+		Key:         key,
+		Reader:      r,
+		ContentType: "text/csv",
+		Size:        size,
+		ACL:         "private",
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Check lists the key prefix, which needs the same credentials and bucket
+// as uploads
+func (t *S3Transport) Check(ctx context.Context, destination types.Destination, creds types.Credentials) error {
+	bucket, err := t.open(destination, creds)
+	if err != nil {
+		return err
+	}
+	_, err = bucket.List(ctx, strings.TrimPrefix(destination.Path, "/"))
+	return err
+}
+
+// List lists the objects directly under the destination's key prefix
+func (t *S3Transport) List(ctx context.Context, destination types.Destination, creds types.Credentials) ([]types.RemoteFile, error) {
+	bucket, err := t.open(destination, creds)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimPrefix(destination.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	objects, err := bucket.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]types.RemoteFile, 0, len(objects))
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, prefix)
+		// Objects in "subdirectories", such as the archive, are not listed
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		files = append(files, types.RemoteFile{Path: object.Key, Name: name, Size: object.Size, ModTime: object.LastModified})
+	}
+	return files, nil
+}
+
+// Fetch downloads the object at remotePath
+func (t *S3Transport) Fetch(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath string, w io.Writer) (int64, error) {
+	bucket, err := t.open(destination, creds)
+	if err != nil {
+		return 0, err
+	}
+	object, err := bucket.Download(ctx, remotePath)
+	if err != nil {
+		return 0, err
+	}
+	defer object.Reader.Close()
+	return io.Copy(w, object.Reader)
+}
+
+// Move copies the object at remotePath to target and deletes it. Keys have
+// no directories to create.
+func (t *S3Transport) Move(ctx context.Context, destination types.Destination, creds types.Credentials, remotePath, target string) error {
+	bucket, err := t.open(destination, creds)
+	if err != nil {
+		return err
+	}
+	object, err := bucket.Download(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer object.Reader.Close()
+
+	_, err = bucket.Upload(ctx, storage.UploadOptions{
+		Key:         strings.TrimPrefix(target, "/"),
+		Reader:      object.Reader,
+		ContentType: object.Metadata.ContentType,
+		Size:        object.Metadata.Size,
+		ACL:         "private",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", remotePath, target, err)
+	}
+	return bucket.Delete(ctx, remotePath)
+}
+
+// validateSSHCredentials parses the host key and credentials the way
+// connections will, so that mistakes surface when the destination is saved
+func validateSSHCredentials(hostKey string, creds types.Credentials) error {
+	if _, err := hostKeyCallback(hostKey); err != nil {
+		return err
+	}
+	_, err := sshAuth(creds)
+	return err
+}
@@ -0,0 +1,217 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DestinationKind is how files reach a destination
+type DestinationKind string
+
+const (
+	DestinationSFTP DestinationKind = "sftp"
+	DestinationS3   DestinationKind = "s3"
+)
+
+// IsValid reports whether the kind is known
+func (k DestinationKind) IsValid() bool {
+	return k == DestinationSFTP || k == DestinationS3
+}
+
+// Credentials authenticate with a destination. They are stored sealed and
+// never returned.
+type Credentials struct {
+	// Password and PrivateKey (PEM, optionally protected by Passphrase)
+	// sign in to SFTP servers; either or both may be given
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	// AccessKey and SecretKey sign S3 requests
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+}
+
+// IsEmpty reports whether no credential is set
+func (c Credentials) IsEmpty() bool {
+	return c == Credentials{}
+}
+
+// Destination is a customer's server or bucket exports are delivered to
+type Destination struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	Name           string          `json:"name"`
+	Kind           DestinationKind `json:"kind"`
+	// Host, Port, Username and HostKey address SFTP servers. HostKey pins
+	// the server's key, as a known_hosts public key or its SHA256
+	// fingerprint; connections to servers presenting another are refused.
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	HostKey  string `json:"host_key,omitempty"`
+	// Bucket, Region and Endpoint address S3 buckets; Endpoint is set for
+	// S3-compatible services other than AWS
+	Bucket   string `json:"bucket,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Path is the directory on the server, or the key prefix in the bucket,
+	// files are written to
+	Path              string     `json:"path"`
+	HasCredentials    bool       `json:"has_credentials"`
+	SealedCredentials []byte     `json:"-"`
+	Active            bool       `json:"active"`
+	CreatedBy         *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// DestinationRequest creates or replaces a destination. On update, nil
+// Credentials keep the stored ones.
+type DestinationRequest struct {
+	Name        string          `json:"name"`
+	Kind        DestinationKind `json:"kind"`
+	Host        string          `json:"host,omitempty"`
+	Port        int             `json:"port,omitempty"`
+	Username    string          `json:"username,omitempty"`
+	HostKey     string          `json:"host_key,omitempty"`
+	Bucket      string          `json:"bucket,omitempty"`
+	Region      string          `json:"region,omitempty"`
+	Endpoint    string          `json:"endpoint,omitempty"`
+	Path        string          `json:"path"`
+	Credentials *Credentials    `json:"credentials,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+}
+
+// DestinationCheck is the outcome of connecting to a destination
+type DestinationCheck struct {
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Frequency is how often a schedule runs
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// IsValid reports whether the frequency is known
+func (f Frequency) IsValid() bool {
+	return f == FrequencyDaily || f == FrequencyWeekly || f == FrequencyMonthly
+}
+
+// TransferStatus is the outcome of an export run
+type TransferStatus string
+
+const (
+	TransferSucceeded TransferStatus = "succeeded"
+	TransferFailed    TransferStatus = "failed"
+)
+
+// TransferTrigger is what started an export run
+type TransferTrigger string
+
+const (
+	TriggerScheduled TransferTrigger = "scheduled"
+	TriggerManual    TransferTrigger = "manual"
+)
+
+// Schedule exports a source as CSV to a destination at a time of day, in the
+// schedule's timezone, every day, week or month
+type Schedule struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	DestinationID  uuid.UUID `json:"destination_id"`
+	Source         string    `json:"source"`
+	// Filename names the uploaded file; {date} and {timestamp} are replaced
+	// with the run's date and time in the schedule's timezone
+	Filename string `json:"filename"`
+	// Incremental exports only hold the rows changed since the last
+	// successful run; the first run exports everything
+	Incremental bool      `json:"incremental"`
+	Frequency   Frequency `json:"frequency"`
+	// TimeOfDay is when the schedule runs, as HH:MM
+	TimeOfDay string `json:"time_of_day"`
+	// Weekday (0 is Sunday) is the day weekly schedules run; DayOfMonth
+	// (1-28) the day monthly schedules run
+	Weekday    *int   `json:"weekday,omitempty"`
+	DayOfMonth *int   `json:"day_of_month,omitempty"`
+	Timezone   string `json:"timezone"`
+	// AlertEmails are emailed when a run fails
+	AlertEmails []string        `json:"alert_emails"`
+	Active      bool            `json:"active"`
+	NextRunAt   *time.Time      `json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time      `json:"last_run_at,omitempty"`
+	LastStatus  *TransferStatus `json:"last_status,omitempty"`
+	// LastSuccessAt is when the last successful run started extracting;
+	// incremental runs export the rows changed after it
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedBy           *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// ScheduleRequest creates or replaces a schedule
+type ScheduleRequest struct {
+	Name          string    `json:"name"`
+	DestinationID uuid.UUID `json:"destination_id"`
+	Source        string    `json:"source"`
+	Filename      string    `json:"filename,omitempty"`
+	Incremental   bool      `json:"incremental"`
+	Frequency     Frequency `json:"frequency"`
+	TimeOfDay     string    `json:"time_of_day"`
+	Weekday       *int      `json:"weekday,omitempty"`
+	DayOfMonth    *int      `json:"day_of_month,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	AlertEmails   []string  `json:"alert_emails,omitempty"`
+	Active        *bool     `json:"active,omitempty"`
+}
+
+// Transfer is the log entry of an export run
+type Transfer struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	ScheduleID     uuid.UUID       `json:"schedule_id"`
+	DestinationID  uuid.UUID       `json:"destination_id"`
+	Source         string          `json:"source"`
+	Trigger        TransferTrigger `json:"trigger"`
+	Status         TransferStatus  `json:"status"`
+	// RemotePath is where the file was written: its path on the server, or
+	// its key in the bucket
+	RemotePath  string     `json:"remote_path"`
+	Rows        int        `json:"rows"`
+	Size        int64      `json:"size"`
+	SHA256      string     `json:"sha256,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  time.Time  `json:"finished_at"`
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty"`
+}
+
+// TransferFilter selects transfer log entries, latest first
+type TransferFilter struct {
+	OrganizationID uuid.UUID
+	ScheduleID     *uuid.UUID
+	Status         *TransferStatus
+	Limit          int
+}
+
+// SourceInfo describes a source schedules can export
+type SourceInfo struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// ExportRun counts the schedules run by a pass over the due schedules
+type ExportRun struct {
+	Ran    int `json:"ran"`
+	Failed int `json:"failed"`
+}
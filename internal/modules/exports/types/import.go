@@ -0,0 +1,182 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportFieldKind is how the text of an import field is read
+type ImportFieldKind string
+
+const (
+	ImportFieldText    ImportFieldKind = "text"
+	ImportFieldBool    ImportFieldKind = "bool"
+	ImportFieldNumber  ImportFieldKind = "number"
+	ImportFieldCountry ImportFieldKind = "country"
+)
+
+// ImportField is a field of an entity files can be imported into
+type ImportField struct {
+	Name string          `json:"name"`
+	Kind ImportFieldKind `json:"kind"`
+	// Required fields must be set on new records
+	Required bool `json:"required"`
+	// Matchable fields can find the existing record a row updates
+	Matchable bool `json:"matchable"`
+}
+
+// ImportEntityInfo describes an entity files can be imported into
+type ImportEntityInfo struct {
+	Code   string        `json:"code"`
+	Name   string        `json:"name"`
+	Fields []ImportField `json:"fields"`
+}
+
+// Mapping is a saved mapping of CSV columns onto an entity's fields
+type Mapping struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Entity         string    `json:"entity"`
+	// Columns maps CSV headers, compared without case, to fields
+	Columns map[string]string `json:"columns"`
+	// MatchField finds the record a row updates; rows matching none create
+	// one. Without it, every row creates a record.
+	MatchField string     `json:"match_field,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// MappingRequest creates or replaces a mapping
+type MappingRequest struct {
+	Name       string            `json:"name"`
+	Entity     string            `json:"entity"`
+	Columns    map[string]string `json:"columns"`
+	MatchField string            `json:"match_field,omitempty"`
+}
+
+// Watcher polls a destination's path for new CSV files, imports them with
+// a mapping and moves them to an archive directory
+type Watcher struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	DestinationID  uuid.UUID `json:"destination_id"`
+	MappingID      uuid.UUID `json:"mapping_id"`
+	// Pattern selects the files to import by name, as in path.Match
+	Pattern string `json:"pattern"`
+	// ArchivePath is the directory, relative to the destination's path,
+	// processed files are moved to
+	ArchivePath string `json:"archive_path"`
+	// ErrorEmails are emailed the row errors of imported files and polling
+	// failures
+	ErrorEmails     []string   `json:"error_emails"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Active          bool       `json:"active"`
+	NextPollAt      *time.Time `json:"next_poll_at,omitempty"`
+	LastPollAt      *time.Time `json:"last_poll_at,omitempty"`
+	// LastStatus is whether the last poll could list and fetch the files
+	LastStatus          *TransferStatus `json:"last_status,omitempty"`
+	LastError           string          `json:"last_error,omitempty"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	CreatedBy           *uuid.UUID      `json:"created_by,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// WatcherRequest creates or replaces a watcher
+type WatcherRequest struct {
+	Name            string    `json:"name"`
+	DestinationID   uuid.UUID `json:"destination_id"`
+	MappingID       uuid.UUID `json:"mapping_id"`
+	Pattern         string    `json:"pattern,omitempty"`
+	ArchivePath     string    `json:"archive_path,omitempty"`
+	ErrorEmails     []string  `json:"error_emails,omitempty"`
+	IntervalMinutes int       `json:"interval_minutes,omitempty"`
+	Active          *bool     `json:"active,omitempty"`
+}
+
+// ImportFileStatus is the outcome of importing a file
+type ImportFileStatus string
+
+const (
+	ImportFileSucceeded ImportFileStatus = "succeeded"
+	// ImportFilePartial files had rows that could not be imported
+	ImportFilePartial ImportFileStatus = "partial"
+	// ImportFileFailed files could not be read, or their import stopped
+	// part way; the file's error says why
+	ImportFileFailed ImportFileStatus = "failed"
+)
+
+// IsValid reports whether the status is known
+func (s ImportFileStatus) IsValid() bool {
+	return s == ImportFileSucceeded || s == ImportFilePartial || s == ImportFileFailed
+}
+
+// RowError is why a row of an imported file was not imported
+type RowError struct {
+	// Line is the row's line in the file; the header is line 1
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportFile is the log entry of a file a watcher imported
+type ImportFile struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	WatcherID      uuid.UUID `json:"watcher_id"`
+	MappingID      uuid.UUID `json:"mapping_id"`
+	Entity         string    `json:"entity"`
+	// RemotePath is where the file was found; ArchivedPath where it was
+	// moved once processed, empty when it could not be moved
+	RemotePath   string           `json:"remote_path"`
+	ArchivedPath string           `json:"archived_path,omitempty"`
+	Size         int64            `json:"size"`
+	SHA256       string           `json:"sha256,omitempty"`
+	Status       ImportFileStatus `json:"status"`
+	Rows         int              `json:"rows"`
+	Created      int              `json:"created"`
+	Updated      int              `json:"updated"`
+	Failed       int              `json:"failed"`
+	Error        string           `json:"error,omitempty"`
+	StartedAt    time.Time        `json:"started_at"`
+	FinishedAt   time.Time        `json:"finished_at"`
+	// Errors are the first row errors; only returned for a single file
+	Errors []RowError `json:"errors,omitempty"`
+}
+
+// ImportFileFilter selects import log entries, latest first
+type ImportFileFilter struct {
+	OrganizationID uuid.UUID
+	WatcherID      *uuid.UUID
+	Status         *ImportFileStatus
+	Limit          int
+}
+
+// WatchRun counts the watchers polled and files imported by a pass over
+// the due watchers
+type WatchRun struct {
+	Polled int `json:"polled"`
+	Failed int `json:"failed"`
+	Files  int `json:"files"`
+}
+
+// WatchPoll is the outcome of polling a watcher on request
+type WatchPoll struct {
+	OK bool `json:"ok"`
+	// Error is why the files could not be reached
+	Error string       `json:"error,omitempty"`
+	Files []ImportFile `json:"files"`
+}
+
+// RemoteFile is a file found in a destination's path
+type RemoteFile struct {
+	// Path is the file's path on the server, or its key in the bucket
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
@@ -12,10 +14,10 @@ import (
 )
 
 type AnalyticsHandler struct {
-	service *service.AnalyticsService
+	service *service.CachedAnalyticsService
 }
 
-func NewAnalyticsHandler(service *service.AnalyticsService) *AnalyticsHandler {
+func NewAnalyticsHandler(service *service.CachedAnalyticsService) *AnalyticsHandler {
 	return &AnalyticsHandler{
 		service: service,
 	}
@@ -56,6 +58,15 @@ func (h *AnalyticsHandler) RegisterRoutes(router *httprouter.Router) {
 	router.GET("/api/inventory/analytics/dashboard", h.GetInventoryDashboard)
 }
 
+// analyticsContext returns the request context, marked to bypass the
+// analytics cache when the request has refresh=true
+func analyticsContext(r *http.Request) context.Context {
+	if refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh")); refresh {
+		return service.WithForcedRefresh(r.Context())
+	}
+	return r.Context()
+}
+
 // GetInventoryValuation handles requests for inventory valuation data
 func (h *AnalyticsHandler) GetInventoryValuation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	// Get organization ID from context
@@ -132,7 +143,7 @@ func (h *AnalyticsHandler) GetValuationSummary(w http.ResponseWriter, r *http.Re
 	}
 
 	// Get summary data
-	summary, err := h.service.GetValuationSummary(r.Context(), orgID)
+	summary, err := h.service.GetValuationSummary(analyticsContext(r), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -306,7 +317,7 @@ func (h *AnalyticsHandler) GetDeadStockSummary(w http.ResponseWriter, r *http.Re
 	}
 
 	// Get dead stock summary
-	summary, err := h.service.GetDeadStockSummary(r.Context(), orgID)
+	summary, err := h.service.GetDeadStockSummary(analyticsContext(r), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -458,7 +469,7 @@ func (h *AnalyticsHandler) GetInventoryDashboard(w http.ResponseWriter, r *http.
 	}
 
 	// Get dashboard data
-	dashboard, err := h.service.GetInventoryDashboard(r.Context(), orgID)
+	dashboard, err := h.service.GetInventoryDashboard(analyticsContext(r), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/auth/middleware"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// AuthService wraps the replenishment and quality control routes with the
+// middleware that puts the caller's organization and user on the request
+// context under the "organization_id" and "user_id" keys their handlers read
+type AuthService interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+// ContextAuthService is the AuthService used when the module is given none;
+// it copies the organization and user resolved by the auth middleware
+type ContextAuthService struct{}
+
+func NewContextAuthService() *ContextAuthService {
+	return &ContextAuthService{}
+}
+
+func (s *ContextAuthService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Organization not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "User not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "organization_id", orgID)
+		ctx = context.WithValue(ctx, "user_id", userID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// routeParam returns a path parameter of a route registered with
+// router.Handler
+func routeParam(r *http.Request, name string) string {
+	return httprouter.ParamsFromContext(r.Context()).ByName(name)
+}
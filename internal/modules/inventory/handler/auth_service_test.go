@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplenishmentRoutesRequireAuthContext(t *testing.T) {
+	router := httprouter.New()
+	authService := NewContextAuthService()
+	require.NotPanics(t, func() {
+		NewReplenishmentHandler(nil, authService).RegisterRoutes(router)
+		NewQualityControlHandler(nil, authService).RegisterRoutes(router)
+		NewBatchOperationHandler(nil).RegisterRoutes(router)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/inventory/replenishment/rules/not-a-uuid", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	ctx := auth.WithAuthContext(req.Context(), &auth.AuthContext{UserID: uuid.New(), OrganizationID: uuid.New()})
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req.WithContext(ctx))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid rule ID")
+}
@@ -11,8 +11,8 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 // BatchOperationHandler handles HTTP requests for batch operations
@@ -26,54 +26,42 @@ func NewBatchOperationHandler(service service.BatchOperationService) *BatchOpera
 	}
 }
 
-func (h *BatchOperationHandler) RegisterRoutes(router *chi.Mux) {
+func (h *BatchOperationHandler) RegisterRoutes(router *httprouter.Router) {
+	route := func(method, path string, handler http.HandlerFunc) {
+		router.Handler(method, "/api/v1/inventory"+path, h.authMiddleware(handler))
+	}
+
 	// Batch Operation CRUD endpoints
-	router.Route("/api/v1/inventory/batch-operations", func(r chi.Router) {
-		r.Use(h.authMiddleware)
-		r.Get("/", h.ListBatchOperations)
-		r.Post("/", h.CreateBatchOperation)
-		r.Get("/{id}", h.GetBatchOperation)
-		r.Put("/{id}", h.UpdateBatchOperation)
-		r.Delete("/{id}", h.DeleteBatchOperation)
-	})
+	route(http.MethodGet, "/batch-operations", h.ListBatchOperations)
+	route(http.MethodPost, "/batch-operations", h.CreateBatchOperation)
+	route(http.MethodGet, "/batch-operations/:id", h.GetBatchOperation)
+	route(http.MethodPut, "/batch-operations/:id", h.UpdateBatchOperation)
+	route(http.MethodDelete, "/batch-operations/:id", h.DeleteBatchOperation)
 
 	// Batch Operation Item endpoints
-	router.Route("/api/v1/inventory/batch-operations/{batchOperationID}/items", func(r chi.Router) {
-		r.Use(h.authMiddleware)
-		r.Get("/", h.ListBatchOperationItems)
-		r.Post("/", h.CreateBatchOperationItem)
-		r.Get("/{id}", h.GetBatchOperationItem)
-		r.Put("/{id}", h.UpdateBatchOperationItem)
-		r.Delete("/{id}", h.DeleteBatchOperationItem)
-	})
+	route(http.MethodGet, "/batch-operations/:id/items", h.ListBatchOperationItems)
+	route(http.MethodPost, "/batch-operations/:id/items", h.CreateBatchOperationItem)
+	route(http.MethodGet, "/batch-operations/:id/items/:itemID", h.GetBatchOperationItem)
+	route(http.MethodPut, "/batch-operations/:id/items/:itemID", h.UpdateBatchOperationItem)
+	route(http.MethodDelete, "/batch-operations/:id/items/:itemID", h.DeleteBatchOperationItem)
 
 	// Specialized Batch Creation endpoints
-	router.Route("/api/v1/inventory/batch-operations", func(r chi.Router) {
-		r.Use(h.authMiddleware)
-		r.Post("/stock-adjustment", h.CreateStockAdjustmentBatch)
-		r.Post("/stock-transfer", h.CreateStockTransferBatch)
-		r.Post("/stock-count", h.CreateStockCountBatch)
-		r.Post("/price-update", h.CreatePriceUpdateBatch)
-		r.Post("/location-update", h.CreateLocationUpdateBatch)
-		r.Post("/status-update", h.CreateStatusUpdateBatch)
-	})
+	route(http.MethodPost, "/stock-adjustment-batches", h.CreateStockAdjustmentBatch)
+	route(http.MethodPost, "/stock-transfer-batches", h.CreateStockTransferBatch)
+	route(http.MethodPost, "/stock-count-batches", h.CreateStockCountBatch)
+	route(http.MethodPost, "/price-update-batches", h.CreatePriceUpdateBatch)
+	route(http.MethodPost, "/location-update-batches", h.CreateLocationUpdateBatch)
+	route(http.MethodPost, "/status-update-batches", h.CreateStatusUpdateBatch)
 
 	// Processing and Statistics endpoints
-	router.Route("/api/v1/inventory/batch-operations", func(r chi.Router) {
-		r.Use(h.authMiddleware)
-		r.Post("/{id}/process", h.ProcessBatchOperation)
-		r.Get("/{id}/items", h.ListBatchOperationItems)
-		r.Get("/{id}/items/{status}", h.ListBatchOperationItemsByStatus)
-		r.Get("/statistics", h.GetBatchOperationStatistics)
-	})
+	route(http.MethodPost, "/batch-operations/:id/process", h.ProcessBatchOperation)
+	route(http.MethodGet, "/batch-operations/:id/items-by-status/:status", h.ListBatchOperationItemsByStatus)
+	route(http.MethodGet, "/batch-operation-statistics", h.GetBatchOperationStatistics)
 
 	// Filtering endpoints
-	router.Route("/api/v1/inventory/batch-operations", func(r chi.Router) {
-		r.Use(h.authMiddleware)
-		r.Get("/by-status/{status}", h.ListBatchOperationsByStatus)
-		r.Get("/by-type/{type}", h.ListBatchOperationsByType)
-		r.Get("/by-product/{productID}", h.ListBatchOperationsByProduct)
-	})
+	route(http.MethodGet, "/batch-operations-by-status/:status", h.ListBatchOperationsByStatus)
+	route(http.MethodGet, "/batch-operations-by-type/:type", h.ListBatchOperationsByType)
+	route(http.MethodGet, "/batch-operations-by-product/:productID", h.ListBatchOperationsByProduct)
 }
 
 func (h *BatchOperationHandler) authMiddleware(next http.Handler) http.Handler {
@@ -124,7 +112,7 @@ func (h *BatchOperationHandler) CreateBatchOperation(w http.ResponseWriter, r *h
 }
 
 func (h *BatchOperationHandler) GetBatchOperation(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
@@ -168,7 +156,7 @@ func (h *BatchOperationHandler) ListBatchOperations(w http.ResponseWriter, r *ht
 }
 
 func (h *BatchOperationHandler) UpdateBatchOperation(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
@@ -193,14 +181,14 @@ func (h *BatchOperationHandler) UpdateBatchOperation(w http.ResponseWriter, r *h
 }
 
 func (h *BatchOperationHandler) DeleteBatchOperation(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.DeleteBatchOperation(r.Context(), id)
+	err = h.service.DeleteBatchOperation(r.Context(), id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete batch operation: %v", err), http.StatusInternalServerError)
 		return
@@ -212,7 +200,7 @@ func (h *BatchOperationHandler) DeleteBatchOperation(w http.ResponseWriter, r *h
 // Batch Operation Item Endpoints
 
 func (h *BatchOperationHandler) CreateBatchOperationItem(w http.ResponseWriter, r *http.Request) {
-	batchOperationIDStr := chi.URLParam(r, "batchOperationID")
+	batchOperationIDStr := routeParam(r, "id")
 	batchOperationID, err := uuid.Parse(batchOperationIDStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
@@ -238,7 +226,7 @@ func (h *BatchOperationHandler) CreateBatchOperationItem(w http.ResponseWriter,
 }
 
 func (h *BatchOperationHandler) GetBatchOperationItem(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "itemID")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation item ID", http.StatusBadRequest)
@@ -260,7 +248,7 @@ func (h *BatchOperationHandler) GetBatchOperationItem(w http.ResponseWriter, r *
 }
 
 func (h *BatchOperationHandler) ListBatchOperationItems(w http.ResponseWriter, r *http.Request) {
-	batchOperationIDStr := chi.URLParam(r, "batchOperationID")
+	batchOperationIDStr := routeParam(r, "id")
 	batchOperationID, err := uuid.Parse(batchOperationIDStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
@@ -278,7 +266,7 @@ func (h *BatchOperationHandler) ListBatchOperationItems(w http.ResponseWriter, r
 }
 
 func (h *BatchOperationHandler) UpdateBatchOperationItem(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "itemID")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation item ID", http.StatusBadRequest)
@@ -303,14 +291,14 @@ func (h *BatchOperationHandler) UpdateBatchOperationItem(w http.ResponseWriter,
 }
 
 func (h *BatchOperationHandler) DeleteBatchOperationItem(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "itemID")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation item ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.service.DeleteBatchOperationItem(r.Context(), id)
+	err = h.service.DeleteBatchOperationItem(r.Context(), id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete batch operation item: %v", err), http.StatusInternalServerError)
 		return
@@ -576,7 +564,7 @@ func (h *BatchOperationHandler) CreateStatusUpdateBatch(w http.ResponseWriter, r
 // Processing and Statistics Endpoints
 
 func (h *BatchOperationHandler) ProcessBatchOperation(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
+	idStr := routeParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
@@ -596,14 +584,14 @@ func (h *BatchOperationHandler) ProcessBatchOperation(w http.ResponseWriter, r *
 }
 
 func (h *BatchOperationHandler) ListBatchOperationItemsByStatus(w http.ResponseWriter, r *http.Request) {
-	batchOperationIDStr := chi.URLParam(r, "id")
+	batchOperationIDStr := routeParam(r, "id")
 	batchOperationID, err := uuid.Parse(batchOperationIDStr)
 	if err != nil {
 		http.Error(w, "Invalid batch operation ID", http.StatusBadRequest)
 		return
 	}
 
-	status := chi.URLParam(r, "status")
+	status := routeParam(r, "status")
 
 	items, err := h.service.ListBatchOperationItemsByStatus(r.Context(), batchOperationID, status)
 	if err != nil {
@@ -663,7 +651,7 @@ func (h *BatchOperationHandler) GetBatchOperationStatistics(w http.ResponseWrite
 
 func (h *BatchOperationHandler) ListBatchOperationsByStatus(w http.ResponseWriter, r *http.Request) {
 	orgID := r.Context().Value("organization_id").(uuid.UUID)
-	status := chi.URLParam(r, "status")
+	status := routeParam(r, "status")
 
 	operations, err := h.service.ListBatchOperationsByStatus(r.Context(), orgID, status)
 	if err != nil {
@@ -677,7 +665,7 @@ func (h *BatchOperationHandler) ListBatchOperationsByStatus(w http.ResponseWrite
 
 func (h *BatchOperationHandler) ListBatchOperationsByType(w http.ResponseWriter, r *http.Request) {
 	orgID := r.Context().Value("organization_id").(uuid.UUID)
-	operationTypeStr := chi.URLParam(r, "type")
+	operationTypeStr := routeParam(r, "type")
 	operationType := types.BatchOperationType(operationTypeStr)
 
 	operations, err := h.service.ListBatchOperationsByType(r.Context(), orgID, operationType)
@@ -692,7 +680,7 @@ func (h *BatchOperationHandler) ListBatchOperationsByType(w http.ResponseWriter,
 
 func (h *BatchOperationHandler) ListBatchOperationsByProduct(w http.ResponseWriter, r *http.Request) {
 	orgID := r.Context().Value("organization_id").(uuid.UUID)
-	productIDStr := chi.URLParam(r, "productID")
+	productIDStr := routeParam(r, "productID")
 	productID, err := uuid.Parse(productIDStr)
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
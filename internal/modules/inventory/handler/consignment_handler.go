@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultStatementRange is the range of a consignment statement without ?from
+const defaultStatementRange = 30 * 24 * time.Hour
+
+type ConsignmentHandler struct {
+	service *service.ConsignmentService
+}
+
+func NewConsignmentHandler(service *service.ConsignmentService) *ConsignmentHandler {
+	return &ConsignmentHandler{
+		service: service,
+	}
+}
+
+func (h *ConsignmentHandler) RegisterRoutes(router *httprouter.Router) {
+	// Agreement endpoints
+	router.GET("/api/inventory/consignment/agreements", h.ListAgreements)
+	router.PUT("/api/inventory/consignment/agreements", h.SaveAgreement)
+	router.GET("/api/inventory/consignment/agreements/:id", h.GetAgreement)
+	router.POST("/api/inventory/consignment/agreements/:id/bill", h.BillAgreement)
+	router.GET("/api/inventory/consignment/agreements/:id/statement", h.GetStatement)
+
+	// Stock endpoints
+	router.POST("/api/inventory/consignment/receipts", h.Receive)
+	router.POST("/api/inventory/consignment/consumptions", h.Consume)
+	router.GET("/api/inventory/consignment/consumptions", h.ListConsumptions)
+	router.GET("/api/inventory/consignment/stock", h.ListOwnership)
+}
+
+// writeConsignmentError maps consignment errors to HTTP statuses
+func writeConsignmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidConsignment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInsufficientConsignedStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrNoPurchaseJournal):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseOptionalUUID parses an optional UUID query parameter
+func parseOptionalUUID(r *http.Request, name string) (*uuid.UUID, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// ListAgreements handles GET /api/inventory/consignment/agreements
+func (h *ConsignmentHandler) ListAgreements(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	agreements, err := h.service.ListAgreements(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, agreements)
+}
+
+// SaveAgreement handles PUT /api/inventory/consignment/agreements, creating
+// or replacing the agreement with the request's vendor
+func (h *ConsignmentHandler) SaveAgreement(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.ConsignmentAgreementRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	agreement, err := h.service.SaveAgreement(r.Context(), authCtx.OrganizationID, request)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	if agreement == nil {
+		http.Error(w, "Vendor not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, agreement)
+}
+
+// GetAgreement handles GET /api/inventory/consignment/agreements/:id
+func (h *ConsignmentHandler) GetAgreement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid agreement ID", http.StatusBadRequest)
+		return
+	}
+
+	agreement, err := h.service.GetAgreement(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	if agreement == nil {
+		http.Error(w, "Consignment agreement not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, agreement)
+}
+
+// BillAgreement handles POST /api/inventory/consignment/agreements/:id/bill,
+// drafting a vendor bill for the agreement's unbilled consumptions
+func (h *ConsignmentHandler) BillAgreement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid agreement ID", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := h.service.BillAgreement(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	if bill == nil {
+		http.Error(w, "Consignment agreement not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, bill)
+}
+
+// GetStatement handles GET /api/inventory/consignment/agreements/:id/statement.
+// ?from and ?to are RFC 3339 timestamps and default to the last 30 days.
+func (h *ConsignmentHandler) GetStatement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid agreement ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid to timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.Add(-defaultStatementRange)
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "Invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	statement, err := h.service.GetStatement(r.Context(), authCtx.OrganizationID, id, from, to)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	if statement == nil {
+		http.Error(w, "Consignment agreement not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, statement)
+}
+
+// Receive handles POST /api/inventory/consignment/receipts
+func (h *ConsignmentHandler) Receive(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.ConsignmentReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.service.Receive(r.Context(), authCtx.OrganizationID, authCtx.UserID, request)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	if receipt == nil {
+		http.Error(w, "Product, internal location or lot not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, receipt)
+}
+
+// Consume handles POST /api/inventory/consignment/consumptions
+func (h *ConsignmentHandler) Consume(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.ConsignmentConsumptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	consumption, err := h.service.Consume(r.Context(), authCtx.OrganizationID, authCtx.UserID, request)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, consumption)
+}
+
+// ListConsumptions handles GET /api/inventory/consignment/consumptions with
+// optional ?agreement_id, ?unbilled=true, ?from, ?to, ?limit and ?offset
+func (h *ConsignmentHandler) ListConsumptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.ConsignmentConsumptionFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Unbilled:       query.Get("unbilled") == "true",
+	}
+	agreementID, err := parseOptionalUUID(r, "agreement_id")
+	if err != nil {
+		http.Error(w, "Invalid agreement ID", http.StatusBadRequest)
+		return
+	}
+	filter.AgreementID = agreementID
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid to timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.To = &to
+	}
+	if v := query.Get("limit"); v != "" {
+		filter.Limit, _ = strconv.Atoi(v)
+	}
+	if v := query.Get("offset"); v != "" {
+		filter.Offset, _ = strconv.Atoi(v)
+	}
+
+	consumptions, err := h.service.ListConsumptions(r.Context(), filter)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, consumptions)
+}
+
+// ListOwnership handles GET /api/inventory/consignment/stock with optional
+// ?product_id, ?location_id, ?owner_id and ?consigned=true
+func (h *ConsignmentHandler) ListOwnership(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.StockOwnershipFilter{
+		OrganizationID: authCtx.OrganizationID,
+		ConsignedOnly:  r.URL.Query().Get("consigned") == "true",
+	}
+	var err error
+	if filter.ProductID, err = parseOptionalUUID(r, "product_id"); err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	if filter.LocationID, err = parseOptionalUUID(r, "location_id"); err != nil {
+		http.Error(w, "Invalid location ID", http.StatusBadRequest)
+		return
+	}
+	if filter.OwnerID, err = parseOptionalUUID(r, "owner_id"); err != nil {
+		http.Error(w, "Invalid owner ID", http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.service.ListOwnership(r.Context(), filter)
+	if err != nil {
+		writeConsignmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stock)
+}
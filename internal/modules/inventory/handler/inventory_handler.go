@@ -342,13 +342,20 @@ func (h *InventoryHandler) GetAvailableQuantity(w http.ResponseWriter, r *http.R
 // Stock Move handlers
 
 func (h *InventoryHandler) CreateMove(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	var req types.StockMove
+	var req types.StockMoveCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	createdMove, err := h.service.CreateMove(r.Context(), req)
+	// Get organization ID from context (set by auth middleware)
+	orgID, ok := r.Context().Value("organizationID").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Organization ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	createdMove, err := h.service.CreateMove(r.Context(), orgID, req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -17,7 +17,7 @@ type ProcurementGroupHandler struct {
 }
 
 // NewProcurementGroupHandler creates a new ProcurementGroupHandler
-type NewProcurementGroupHandler(service *service.ProcurementGroupService) *ProcurementGroupHandler {
+func NewProcurementGroupHandler(service *service.ProcurementGroupService) *ProcurementGroupHandler {
 	return &ProcurementGroupHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *ProcurementGroupHandler) GetByID(w http.ResponseWriter, r *http.Request
 
 // List handles listing all procurement groups
 func (h *ProcurementGroupHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
@@ -1,104 +1,105 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	calibrationservice "github.com/KevTiv/alieze-erp/internal/modules/calibration/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	trainingservice "github.com/KevTiv/alieze-erp/internal/modules/training/service"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 type QualityControlHandler struct {
 	qualityControlService *service.QualityControlService
-	authService          auth.AuthService
+	authService           AuthService
 }
 
 func NewQualityControlHandler(
 	qualityControlService *service.QualityControlService,
-	authService auth.AuthService,
+	authService AuthService,
 ) *QualityControlHandler {
 	return &QualityControlHandler{
 		qualityControlService: qualityControlService,
-		authService:          authService,
+		authService:           authService,
 	}
 }
 
-func (h *QualityControlHandler) RegisterRoutes(router chi.Router) {
-	router.Route("/quality-control", func(r chi.Router) {
-		r.Use(h.authService.Middleware)
-
-		// Inspection Management
-		r.Route("/inspections", func(ri chi.Router) {
-			ri.Post("/", h.CreateInspection)
-			ri.Get("/", h.ListInspections)
-			ri.Get("/{inspectionID}", h.GetInspection)
-			ri.Put("/{inspectionID}", h.UpdateInspection)
-			ri.Delete("/{inspectionID}", h.DeleteInspection)
-			ri.Get("/product/{productID}", h.ListInspectionsByProduct)
-			ri.Get("/status/{status}", h.ListInspectionsByStatus)
-			ri.Post("/from-stock-move", h.CreateInspectionFromStockMove)
-			ri.Post("/{inspectionID}/status", h.UpdateInspectionStatus)
-			ri.Post("/{inspectionID}/complete", h.CompleteInspection)
-			ri.Post("/{inspectionID}/disposition", h.HandleDisposition)
-		})
-
-		// Checklist Management
-		r.Route("/checklists", func(rc chi.Router) {
-			rc.Post("/", h.CreateChecklist)
-			rc.Get("/", h.ListChecklists)
-			rc.Get("/{checklistID}", h.GetChecklist)
-			rc.Put("/{checklistID}", h.UpdateChecklist)
-			rc.Delete("/{checklistID}", h.DeleteChecklist)
-			rc.Get("/active", h.ListActiveChecklists)
-			rc.Get("/product/{productID}", h.ListChecklistsByProduct)
-		})
-
-		// Checklist Item Management
-		r.Route("/checklist-items", func(rci chi.Router) {
-			rci.Post("/", h.CreateChecklistItem)
-			rci.Get("/{itemID}", h.GetChecklistItem)
-			rci.Put("/{itemID}", h.UpdateChecklistItem)
-			rci.Delete("/{itemID}", h.DeleteChecklistItem)
-			rci.Get("/checklist/{checklistID}", h.ListChecklistItems)
-			rci.Get("/checklist/{checklistID}/active", h.ListActiveChecklistItems)
-		})
-
-		// Inspection Item Management
-		r.Route("/inspection-items", func(rii chi.Router) {
-			rii.Post("/", h.CreateInspectionItem)
-			rii.Get("/{itemID}", h.GetInspectionItem)
-			rii.Put("/{itemID}", h.UpdateInspectionItem)
-			rii.Delete("/{itemID}", h.DeleteInspectionItem)
-			rii.Get("/inspection/{inspectionID}", h.ListInspectionItems)
-			rii.Post("/{itemID}/result", h.UpdateInspectionItemResult)
-		})
-
-		// Alert Management
-		r.Route("/alerts", func(ra chi.Router) {
-			ra.Post("/", h.CreateAlert)
-			ra.Get("/", h.ListAlerts)
-			ra.Get("/{alertID}", h.GetAlert)
-			ra.Put("/{alertID}", h.UpdateAlert)
-			ra.Delete("/{alertID}", h.DeleteAlert)
-			ra.Get("/open", h.ListOpenAlerts)
-			ra.Post("/{alertID}/status", h.UpdateAlertStatus)
-			ra.Post("/from-inspection", h.CreateAlertFromInspection)
-		})
-
-		// Workflow Endpoints
-		r.Post("/workflow/start", h.StartQualityControlWorkflow)
-		r.Post("/workflow/process", h.ProcessQualityControlResult)
-
-		// Dashboard and Statistics
-		r.Get("/statistics", h.GetQualityControlStatistics)
-		r.Get("/dashboard", h.GetQualityControlDashboard)
-	})
+func (h *QualityControlHandler) RegisterRoutes(router *httprouter.Router) {
+	route := func(method, path string, handler http.HandlerFunc) {
+		router.Handler(method, "/api/inventory/quality-control"+path, h.authService.Middleware(handler))
+	}
+
+	// Inspection Management
+	route(http.MethodPost, "/inspections", h.CreateInspection)
+	route(http.MethodGet, "/inspections", h.ListInspections)
+	route(http.MethodGet, "/inspections/:inspectionID", h.GetInspection)
+	route(http.MethodPut, "/inspections/:inspectionID", h.UpdateInspection)
+	route(http.MethodDelete, "/inspections/:inspectionID", h.DeleteInspection)
+	route(http.MethodGet, "/inspections-by-product/:productID", h.ListInspectionsByProduct)
+	route(http.MethodGet, "/inspections-by-status/:status", h.ListInspectionsByStatus)
+	route(http.MethodPost, "/inspections-from-stock-move", h.CreateInspectionFromStockMove)
+	route(http.MethodPost, "/inspections/:inspectionID/status", h.UpdateInspectionStatus)
+	route(http.MethodPost, "/inspections/:inspectionID/complete", h.CompleteInspection)
+	route(http.MethodPost, "/inspections/:inspectionID/disposition", h.HandleDisposition)
+
+	// Checklist Management
+	route(http.MethodPost, "/checklists", h.CreateChecklist)
+	route(http.MethodGet, "/checklists", h.ListChecklists)
+	route(http.MethodGet, "/checklists/:checklistID", h.GetChecklist)
+	route(http.MethodPut, "/checklists/:checklistID", h.UpdateChecklist)
+	route(http.MethodDelete, "/checklists/:checklistID", h.DeleteChecklist)
+	route(http.MethodGet, "/active-checklists", h.ListActiveChecklists)
+	route(http.MethodGet, "/checklists-by-product/:productID", h.ListChecklistsByProduct)
+
+	// Checklist Item Management
+	route(http.MethodPost, "/checklist-items", h.CreateChecklistItem)
+	route(http.MethodGet, "/checklist-items/:itemID", h.GetChecklistItem)
+	route(http.MethodPut, "/checklist-items/:itemID", h.UpdateChecklistItem)
+	route(http.MethodDelete, "/checklist-items/:itemID", h.DeleteChecklistItem)
+	route(http.MethodGet, "/checklists/:checklistID/items", h.ListChecklistItems)
+	route(http.MethodGet, "/checklists/:checklistID/active-items", h.ListActiveChecklistItems)
+
+	// Inspection Item Management
+	route(http.MethodPost, "/inspection-items", h.CreateInspectionItem)
+	route(http.MethodGet, "/inspection-items/:itemID", h.GetInspectionItem)
+	route(http.MethodPut, "/inspection-items/:itemID", h.UpdateInspectionItem)
+	route(http.MethodDelete, "/inspection-items/:itemID", h.DeleteInspectionItem)
+	route(http.MethodGet, "/inspections/:inspectionID/items", h.ListInspectionItems)
+	route(http.MethodPost, "/inspection-items/:itemID/result", h.UpdateInspectionItemResult)
+
+	// Alert Management
+	route(http.MethodPost, "/alerts", h.CreateAlert)
+	route(http.MethodGet, "/alerts", h.ListAlerts)
+	route(http.MethodGet, "/alerts/:alertID", h.GetAlert)
+	route(http.MethodPut, "/alerts/:alertID", h.UpdateAlert)
+	route(http.MethodDelete, "/alerts/:alertID", h.DeleteAlert)
+	route(http.MethodGet, "/open-alerts", h.ListOpenAlerts)
+	route(http.MethodPost, "/alerts/:alertID/status", h.UpdateAlertStatus)
+	route(http.MethodPost, "/alerts-from-inspection", h.CreateAlertFromInspection)
+
+	// Workflow Endpoints
+	route(http.MethodPost, "/workflow/start", h.StartQualityControlWorkflow)
+	route(http.MethodPost, "/workflow/process", h.ProcessQualityControlResult)
+
+	// Dashboard and Statistics
+	route(http.MethodGet, "/statistics", h.GetQualityControlStatistics)
+	route(http.MethodGet, "/dashboard", h.GetQualityControlDashboard)
+}
+
+// inspectionErrorStatus maps assigning an uncertified inspector, or
+// measuring with uncalibrated equipment, to 422
+func inspectionErrorStatus(err error) int {
+	if errors.Is(err, trainingservice.ErrNotQualified) || errors.Is(err, calibrationservice.ErrNotCalibrated) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
 }
 
 // Inspection Handlers
@@ -122,7 +123,7 @@ func (h *QualityControlHandler) CreateInspection(w http.ResponseWriter, r *http.
 
 	createdInspection, err := h.qualityControlService.CreateInspection(ctx, inspection)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -132,7 +133,7 @@ func (h *QualityControlHandler) CreateInspection(w http.ResponseWriter, r *http.
 func (h *QualityControlHandler) GetInspection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -184,7 +185,7 @@ func (h *QualityControlHandler) ListInspectionsByProduct(w http.ResponseWriter,
 		return
 	}
 
-	productIDStr := chi.URLParam(r, "productID")
+	productIDStr := routeParam(r, "productID")
 	productID, err := uuid.Parse(productIDStr)
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
@@ -209,7 +210,7 @@ func (h *QualityControlHandler) ListInspectionsByStatus(w http.ResponseWriter, r
 		return
 	}
 
-	status := chi.URLParam(r, "status")
+	status := routeParam(r, "status")
 
 	inspections, err := h.qualityControlService.ListInspectionsByStatus(ctx, orgID, status)
 	if err != nil {
@@ -223,7 +224,7 @@ func (h *QualityControlHandler) ListInspectionsByStatus(w http.ResponseWriter, r
 func (h *QualityControlHandler) UpdateInspection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -240,7 +241,7 @@ func (h *QualityControlHandler) UpdateInspection(w http.ResponseWriter, r *http.
 
 	updatedInspection, err := h.qualityControlService.UpdateInspection(ctx, inspection)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -250,14 +251,14 @@ func (h *QualityControlHandler) UpdateInspection(w http.ResponseWriter, r *http.
 func (h *QualityControlHandler) DeleteInspection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.qualityControlService.DeleteInspection(ctx, inspectionID)
+	err = h.qualityControlService.DeleteInspection(ctx, inspectionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -297,7 +298,7 @@ func (h *QualityControlHandler) CreateChecklist(w http.ResponseWriter, r *http.R
 func (h *QualityControlHandler) GetChecklist(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	checklistIDStr := chi.URLParam(r, "checklistID")
+	checklistIDStr := routeParam(r, "checklistID")
 	checklistID, err := uuid.Parse(checklistIDStr)
 	if err != nil {
 		http.Error(w, "Invalid checklist ID", http.StatusBadRequest)
@@ -358,7 +359,7 @@ func (h *QualityControlHandler) ListChecklistsByProduct(w http.ResponseWriter, r
 		return
 	}
 
-	productIDStr := chi.URLParam(r, "productID")
+	productIDStr := routeParam(r, "productID")
 	productID, err := uuid.Parse(productIDStr)
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
@@ -377,7 +378,7 @@ func (h *QualityControlHandler) ListChecklistsByProduct(w http.ResponseWriter, r
 func (h *QualityControlHandler) UpdateChecklist(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	checklistIDStr := chi.URLParam(r, "checklistID")
+	checklistIDStr := routeParam(r, "checklistID")
 	checklistID, err := uuid.Parse(checklistIDStr)
 	if err != nil {
 		http.Error(w, "Invalid checklist ID", http.StatusBadRequest)
@@ -404,14 +405,14 @@ func (h *QualityControlHandler) UpdateChecklist(w http.ResponseWriter, r *http.R
 func (h *QualityControlHandler) DeleteChecklist(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	checklistIDStr := chi.URLParam(r, "checklistID")
+	checklistIDStr := routeParam(r, "checklistID")
 	checklistID, err := uuid.Parse(checklistIDStr)
 	if err != nil {
 		http.Error(w, "Invalid checklist ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.qualityControlService.DeleteChecklist(ctx, checklistID)
+	err = h.qualityControlService.DeleteChecklist(ctx, checklistID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -443,7 +444,7 @@ func (h *QualityControlHandler) CreateChecklistItem(w http.ResponseWriter, r *ht
 func (h *QualityControlHandler) GetChecklistItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
@@ -462,7 +463,7 @@ func (h *QualityControlHandler) GetChecklistItem(w http.ResponseWriter, r *http.
 func (h *QualityControlHandler) ListChecklistItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	checklistIDStr := chi.URLParam(r, "checklistID")
+	checklistIDStr := routeParam(r, "checklistID")
 	checklistID, err := uuid.Parse(checklistIDStr)
 	if err != nil {
 		http.Error(w, "Invalid checklist ID", http.StatusBadRequest)
@@ -481,7 +482,7 @@ func (h *QualityControlHandler) ListChecklistItems(w http.ResponseWriter, r *htt
 func (h *QualityControlHandler) ListActiveChecklistItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	checklistIDStr := chi.URLParam(r, "checklistID")
+	checklistIDStr := routeParam(r, "checklistID")
 	checklistID, err := uuid.Parse(checklistIDStr)
 	if err != nil {
 		http.Error(w, "Invalid checklist ID", http.StatusBadRequest)
@@ -500,7 +501,7 @@ func (h *QualityControlHandler) ListActiveChecklistItems(w http.ResponseWriter,
 func (h *QualityControlHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
@@ -527,14 +528,14 @@ func (h *QualityControlHandler) UpdateChecklistItem(w http.ResponseWriter, r *ht
 func (h *QualityControlHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.qualityControlService.DeleteChecklistItem(ctx, itemID)
+	err = h.qualityControlService.DeleteChecklistItem(ctx, itemID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -556,7 +557,7 @@ func (h *QualityControlHandler) CreateInspectionItem(w http.ResponseWriter, r *h
 
 	createdItem, err := h.qualityControlService.CreateInspectionItem(ctx, item)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -566,7 +567,7 @@ func (h *QualityControlHandler) CreateInspectionItem(w http.ResponseWriter, r *h
 func (h *QualityControlHandler) GetInspectionItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
@@ -585,7 +586,7 @@ func (h *QualityControlHandler) GetInspectionItem(w http.ResponseWriter, r *http
 func (h *QualityControlHandler) ListInspectionItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -604,7 +605,7 @@ func (h *QualityControlHandler) ListInspectionItems(w http.ResponseWriter, r *ht
 func (h *QualityControlHandler) UpdateInspectionItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
@@ -621,7 +622,7 @@ func (h *QualityControlHandler) UpdateInspectionItem(w http.ResponseWriter, r *h
 
 	updatedItem, err := h.qualityControlService.UpdateInspectionItem(ctx, item)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -631,7 +632,7 @@ func (h *QualityControlHandler) UpdateInspectionItem(w http.ResponseWriter, r *h
 func (h *QualityControlHandler) UpdateInspectionItemResult(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
@@ -665,14 +666,14 @@ func (h *QualityControlHandler) UpdateInspectionItemResult(w http.ResponseWriter
 func (h *QualityControlHandler) DeleteInspectionItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	itemIDStr := chi.URLParam(r, "itemID")
+	itemIDStr := routeParam(r, "itemID")
 	itemID, err := uuid.Parse(itemIDStr)
 	if err != nil {
 		http.Error(w, "Invalid item ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.qualityControlService.DeleteInspectionItem(ctx, itemID)
+	err = h.qualityControlService.DeleteInspectionItem(ctx, itemID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -712,7 +713,7 @@ func (h *QualityControlHandler) CreateAlert(w http.ResponseWriter, r *http.Reque
 func (h *QualityControlHandler) GetAlert(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	alertIDStr := chi.URLParam(r, "alertID")
+	alertIDStr := routeParam(r, "alertID")
 	alertID, err := uuid.Parse(alertIDStr)
 	if err != nil {
 		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
@@ -767,7 +768,7 @@ func (h *QualityControlHandler) ListOpenAlerts(w http.ResponseWriter, r *http.Re
 func (h *QualityControlHandler) UpdateAlert(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	alertIDStr := chi.URLParam(r, "alertID")
+	alertIDStr := routeParam(r, "alertID")
 	alertID, err := uuid.Parse(alertIDStr)
 	if err != nil {
 		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
@@ -794,7 +795,7 @@ func (h *QualityControlHandler) UpdateAlert(w http.ResponseWriter, r *http.Reque
 func (h *QualityControlHandler) UpdateAlertStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	alertIDStr := chi.URLParam(r, "alertID")
+	alertIDStr := routeParam(r, "alertID")
 	alertID, err := uuid.Parse(alertIDStr)
 	if err != nil {
 		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
@@ -821,7 +822,7 @@ func (h *QualityControlHandler) UpdateAlertStatus(w http.ResponseWriter, r *http
 		resolvedBy = &userID
 	}
 
-	err := h.qualityControlService.UpdateAlertStatus(ctx, alertID, request.Status, resolvedBy)
+	err = h.qualityControlService.UpdateAlertStatus(ctx, alertID, request.Status, resolvedBy)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -833,14 +834,14 @@ func (h *QualityControlHandler) UpdateAlertStatus(w http.ResponseWriter, r *http
 func (h *QualityControlHandler) DeleteAlert(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	alertIDStr := chi.URLParam(r, "alertID")
+	alertIDStr := routeParam(r, "alertID")
 	alertID, err := uuid.Parse(alertIDStr)
 	if err != nil {
 		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
 		return
 	}
 
-	err := h.qualityControlService.DeleteAlert(ctx, alertID)
+	err = h.qualityControlService.DeleteAlert(ctx, alertID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -872,7 +873,7 @@ func (h *QualityControlHandler) CreateInspectionFromStockMove(w http.ResponseWri
 		request.InspectionMethod, request.SampleSize,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -882,7 +883,7 @@ func (h *QualityControlHandler) CreateInspectionFromStockMove(w http.ResponseWri
 func (h *QualityControlHandler) UpdateInspectionStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -907,8 +908,8 @@ func (h *QualityControlHandler) UpdateInspectionStatus(w http.ResponseWriter, r
 	err = h.qualityControlService.UpdateInspectionStatus(
 		ctx, inspectionID, request.Status,
 		getStringValue(request.DefectType), getStringValue(request.DefectDescription),
-		request.DefectQuantity, request.QualityRating, getStringValue(request.ComplianceNotes),
-		getStringValue(request.Disposition),
+		request.DefectQuantity, request.QualityRating, request.ComplianceNotes,
+		request.Disposition,
 	)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -921,7 +922,7 @@ func (h *QualityControlHandler) UpdateInspectionStatus(w http.ResponseWriter, r
 func (h *QualityControlHandler) CompleteInspection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -940,7 +941,7 @@ func (h *QualityControlHandler) CompleteInspection(w http.ResponseWriter, r *htt
 
 	err = h.qualityControlService.CompleteInspection(ctx, inspectionID, request.Status, request.Results)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
@@ -950,7 +951,7 @@ func (h *QualityControlHandler) CompleteInspection(w http.ResponseWriter, r *htt
 func (h *QualityControlHandler) HandleDisposition(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	inspectionIDStr := chi.URLParam(r, "inspectionID")
+	inspectionIDStr := routeParam(r, "inspectionID")
 	inspectionID, err := uuid.Parse(inspectionIDStr)
 	if err != nil {
 		http.Error(w, "Invalid inspection ID", http.StatusBadRequest)
@@ -1019,7 +1020,7 @@ func (h *QualityControlHandler) StartQualityControlWorkflow(w http.ResponseWrite
 
 	inspection, err := h.qualityControlService.StartQualityControlWorkflow(ctx, request.StockMoveID, request.InspectorID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), inspectionErrorStatus(err))
 		return
 	}
 
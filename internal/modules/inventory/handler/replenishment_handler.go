@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -9,52 +8,51 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 )
 
 type ReplenishmentHandler struct {
 	replenishmentService *service.ReplenishmentService
+	authService          AuthService
 }
 
 func NewReplenishmentHandler(
 	replenishmentService *service.ReplenishmentService,
+	authService AuthService,
 ) *ReplenishmentHandler {
 	return &ReplenishmentHandler{
 		replenishmentService: replenishmentService,
+		authService:          authService,
 	}
 }
 
-func (h *ReplenishmentHandler) RegisterRoutes(router chi.Router) {
-	router.Route("/replenishment", func(r chi.Router) {
-		r.Use(h.authService.Middleware)
-
-		// Replenishment Rules
-		r.Route("/rules", func(rr chi.Router) {
-			rr.Post("/", h.CreateReplenishmentRule)
-			rr.Get("/", h.ListReplenishmentRules)
-			rr.Get("/{ruleID}", h.GetReplenishmentRule)
-			rr.Put("/{ruleID}", h.UpdateReplenishmentRule)
-			rr.Delete("/{ruleID}", h.DeleteReplenishmentRule)
-		})
-
-		// Replenishment Orders
-		r.Route("/orders", func(ro chi.Router) {
-			ro.Post("/", h.CreateReplenishmentOrder)
-			ro.Get("/", h.ListReplenishmentOrders)
-			ro.Get("/{orderID}", h.GetReplenishmentOrder)
-			ro.Put("/{orderID}", h.UpdateReplenishmentOrder)
-			ro.Delete("/{orderID}", h.DeleteReplenishmentOrder)
-			ro.Get("/status/{status}", h.ListReplenishmentOrdersByStatus)
-		})
-
-		// Replenishment Processing
-		r.Post("/check", h.CheckAndCreateReplenishmentOrders)
-		r.Post("/process", h.ProcessReplenishmentOrders)
-		r.Post("/cycle", h.RunReplenishmentCycle)
-		r.Get("/statistics", h.GetReplenishmentStatistics)
-		r.Post("/manual-check", h.CheckReplenishmentNeeds)
-	})
+func (h *ReplenishmentHandler) RegisterRoutes(router *httprouter.Router) {
+	route := func(method, path string, handler http.HandlerFunc) {
+		router.Handler(method, "/api/inventory/replenishment"+path, h.authService.Middleware(handler))
+	}
+
+	// Replenishment Rules
+	route(http.MethodPost, "/rules", h.CreateReplenishmentRule)
+	route(http.MethodGet, "/rules", h.ListReplenishmentRules)
+	route(http.MethodGet, "/rules/:ruleID", h.GetReplenishmentRule)
+	route(http.MethodPut, "/rules/:ruleID", h.UpdateReplenishmentRule)
+	route(http.MethodDelete, "/rules/:ruleID", h.DeleteReplenishmentRule)
+
+	// Replenishment Orders
+	route(http.MethodPost, "/orders", h.CreateReplenishmentOrder)
+	route(http.MethodGet, "/orders", h.ListReplenishmentOrders)
+	route(http.MethodGet, "/orders/:orderID", h.GetReplenishmentOrder)
+	route(http.MethodPut, "/orders/:orderID", h.UpdateReplenishmentOrder)
+	route(http.MethodDelete, "/orders/:orderID", h.DeleteReplenishmentOrder)
+	route(http.MethodGet, "/orders-by-status/:status", h.ListReplenishmentOrdersByStatus)
+
+	// Replenishment Processing
+	route(http.MethodPost, "/check", h.CheckAndCreateReplenishmentOrders)
+	route(http.MethodPost, "/process", h.ProcessReplenishmentOrders)
+	route(http.MethodPost, "/cycle", h.RunReplenishmentCycle)
+	route(http.MethodGet, "/statistics", h.GetReplenishmentStatistics)
+	route(http.MethodPost, "/manual-check", h.CheckReplenishmentNeeds)
 }
 
 // Replenishment Rule Handlers
@@ -88,7 +86,7 @@ func (h *ReplenishmentHandler) CreateReplenishmentRule(w http.ResponseWriter, r
 func (h *ReplenishmentHandler) GetReplenishmentRule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	ruleIDStr := chi.URLParam(r, "ruleID")
+	ruleIDStr := routeParam(r, "ruleID")
 	ruleID, err := uuid.Parse(ruleIDStr)
 	if err != nil {
 		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
@@ -125,7 +123,7 @@ func (h *ReplenishmentHandler) ListReplenishmentRules(w http.ResponseWriter, r *
 func (h *ReplenishmentHandler) UpdateReplenishmentRule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	ruleIDStr := chi.URLParam(r, "ruleID")
+	ruleIDStr := routeParam(r, "ruleID")
 	ruleID, err := uuid.Parse(ruleIDStr)
 	if err != nil {
 		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
@@ -152,7 +150,7 @@ func (h *ReplenishmentHandler) UpdateReplenishmentRule(w http.ResponseWriter, r
 func (h *ReplenishmentHandler) DeleteReplenishmentRule(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	ruleIDStr := chi.URLParam(r, "ruleID")
+	ruleIDStr := routeParam(r, "ruleID")
 	ruleID, err := uuid.Parse(ruleIDStr)
 	if err != nil {
 		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
@@ -199,7 +197,7 @@ func (h *ReplenishmentHandler) CreateReplenishmentOrder(w http.ResponseWriter, r
 func (h *ReplenishmentHandler) GetReplenishmentOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	orderIDStr := chi.URLParam(r, "orderID")
+	orderIDStr := routeParam(r, "orderID")
 	orderID, err := uuid.Parse(orderIDStr)
 	if err != nil {
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
@@ -242,7 +240,7 @@ func (h *ReplenishmentHandler) ListReplenishmentOrdersByStatus(w http.ResponseWr
 		return
 	}
 
-	status := chi.URLParam(r, "status")
+	status := routeParam(r, "status")
 
 	orders, err := h.replenishmentService.ListReplenishmentOrdersByStatus(ctx, orgID, status)
 	if err != nil {
@@ -256,7 +254,7 @@ func (h *ReplenishmentHandler) ListReplenishmentOrdersByStatus(w http.ResponseWr
 func (h *ReplenishmentHandler) UpdateReplenishmentOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	orderIDStr := chi.URLParam(r, "orderID")
+	orderIDStr := routeParam(r, "orderID")
 	orderID, err := uuid.Parse(orderIDStr)
 	if err != nil {
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
@@ -283,7 +281,7 @@ func (h *ReplenishmentHandler) UpdateReplenishmentOrder(w http.ResponseWriter, r
 func (h *ReplenishmentHandler) DeleteReplenishmentOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	orderIDStr := chi.URLParam(r, "orderID")
+	orderIDStr := routeParam(r, "orderID")
 	orderID, err := uuid.Parse(orderIDStr)
 	if err != nil {
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
@@ -408,10 +406,3 @@ func (h *ReplenishmentHandler) CheckReplenishmentNeeds(w http.ResponseWriter, r
 
 	respondWithJSON(w, http.StatusOK, results)
 }
-
-// Helper function
-func respondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
-}
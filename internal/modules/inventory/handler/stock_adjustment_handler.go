@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultAdjustmentReportRange is the range of an adjustment report without ?from
+const defaultAdjustmentReportRange = 30 * 24 * time.Hour
+
+type StockAdjustmentHandler struct {
+	service *service.StockAdjustmentService
+}
+
+func NewStockAdjustmentHandler(service *service.StockAdjustmentService) *StockAdjustmentHandler {
+	return &StockAdjustmentHandler{
+		service: service,
+	}
+}
+
+func (h *StockAdjustmentHandler) RegisterRoutes(router *httprouter.Router) {
+	// Stock adjustment endpoints
+	router.POST("/api/inventory/adjustments", h.RequestAdjustment)
+	router.GET("/api/inventory/adjustments", h.ListAdjustments)
+	router.GET("/api/inventory/adjustments/:id", h.GetAdjustment)
+	router.POST("/api/inventory/adjustments/:id/approve", h.ApproveAdjustment)
+	router.POST("/api/inventory/adjustments/:id/reject", h.RejectAdjustment)
+
+	// Reason code and approval settings endpoints
+	router.GET("/api/inventory/adjustment-reasons", h.ListReasons)
+	router.PUT("/api/inventory/adjustment-reasons", h.SaveReason)
+	router.GET("/api/inventory/adjustment-settings", h.GetApprovalSettings)
+	router.PUT("/api/inventory/adjustment-settings", h.UpdateApprovalSettings)
+
+	// Shrinkage report endpoint
+	router.GET("/api/inventory/adjustment-report", h.GetReport)
+}
+
+// writeAdjustmentError maps stock adjustment errors to HTTP statuses
+func writeAdjustmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAdjustment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAdjustmentNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RequestAdjustment handles POST /api/inventory/adjustments. The adjustment
+// is applied at once below the approval threshold, and pending otherwise.
+func (h *StockAdjustmentHandler) RequestAdjustment(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.StockAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.service.RequestAdjustment(r.Context(), authCtx.OrganizationID, authCtx.UserID, request)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	if adjustment == nil {
+		http.Error(w, "Product or internal location not found", http.StatusNotFound)
+		return
+	}
+
+	status := http.StatusCreated
+	if adjustment.Status == types.StockAdjustmentStatusPending {
+		status = http.StatusAccepted
+	}
+	respondWithJSON(w, status, adjustment)
+}
+
+// ListAdjustments handles GET /api/inventory/adjustments with optional
+// ?status, ?reason_code, ?product_id, ?location_id, ?limit and ?offset
+func (h *StockAdjustmentHandler) ListAdjustments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.StockAdjustmentFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.StockAdjustmentStatus(query.Get("status")),
+		ReasonCode:     query.Get("reason_code"),
+	}
+	if v := query.Get("product_id"); v != "" {
+		productID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid product ID", http.StatusBadRequest)
+			return
+		}
+		filter.ProductID = &productID
+	}
+	if v := query.Get("location_id"); v != "" {
+		locationID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+		filter.LocationID = &locationID
+	}
+	if v := query.Get("limit"); v != "" {
+		filter.Limit, _ = strconv.Atoi(v)
+	}
+	if v := query.Get("offset"); v != "" {
+		filter.Offset, _ = strconv.Atoi(v)
+	}
+
+	adjustments, err := h.service.ListAdjustments(r.Context(), filter)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, adjustments)
+}
+
+// GetAdjustment handles GET /api/inventory/adjustments/:id
+func (h *StockAdjustmentHandler) GetAdjustment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid adjustment ID", http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.service.GetAdjustment(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	if adjustment == nil {
+		http.Error(w, "Stock adjustment not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, adjustment)
+}
+
+// ApproveAdjustment handles POST /api/inventory/adjustments/:id/approve
+func (h *StockAdjustmentHandler) ApproveAdjustment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewAdjustment(w, r, ps, h.service.ApproveAdjustment)
+}
+
+// RejectAdjustment handles POST /api/inventory/adjustments/:id/reject
+func (h *StockAdjustmentHandler) RejectAdjustment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewAdjustment(w, r, ps, h.service.RejectAdjustment)
+}
+
+type adjustmentReviewFunc func(ctx context.Context, orgID, id, userID uuid.UUID, review types.StockAdjustmentReview) (*types.StockAdjustment, error)
+
+func (h *StockAdjustmentHandler) reviewAdjustment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, review adjustmentReviewFunc) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid adjustment ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.StockAdjustmentReview
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	adjustment, err := review(r.Context(), authCtx.OrganizationID, id, authCtx.UserID, request)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	if adjustment == nil {
+		http.Error(w, "Stock adjustment not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, adjustment)
+}
+
+// ListReasons handles GET /api/inventory/adjustment-reasons
+func (h *StockAdjustmentHandler) ListReasons(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	reasons, err := h.service.ListReasons(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reasons)
+}
+
+// SaveReason handles PUT /api/inventory/adjustment-reasons, creating or
+// updating the organization's reason with the request's code
+func (h *StockAdjustmentHandler) SaveReason(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.AdjustmentReasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reason, err := h.service.SaveReason(r.Context(), authCtx.OrganizationID, request)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reason)
+}
+
+// GetApprovalSettings handles GET /api/inventory/adjustment-settings
+func (h *StockAdjustmentHandler) GetApprovalSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetApprovalSettings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UpdateApprovalSettings handles PUT /api/inventory/adjustment-settings
+func (h *StockAdjustmentHandler) UpdateApprovalSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var settings types.AdjustmentApprovalSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateApprovalSettings(r.Context(), authCtx.OrganizationID, settings)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// GetReport handles GET /api/inventory/adjustment-report. ?from and ?to are
+// RFC 3339 timestamps and default to the last 30 days; ?period is day, week
+// or month; ?location_id limits the report to one location.
+func (h *StockAdjustmentHandler) GetReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.AdjustmentReportFilter{
+		OrganizationID: authCtx.OrganizationID,
+		To:             time.Now(),
+		Period:         query.Get("period"),
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid to timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.To = to
+	}
+	filter.From = filter.To.Add(-defaultAdjustmentReportRange)
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.From = from
+	}
+	if v := query.Get("location_id"); v != "" {
+		locationID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+		filter.LocationID = &locationID
+	}
+
+	report, err := h.service.GetReport(r.Context(), filter)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -17,7 +17,7 @@ type StockLotHandler struct {
 }
 
 // NewStockLotHandler creates a new StockLotHandler
-type NewStockLotHandler(service *service.StockLotService) *StockLotHandler {
+func NewStockLotHandler(service *service.StockLotService) *StockLotHandler {
 	return &StockLotHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *StockLotHandler) GetByID(w http.ResponseWriter, r *http.Request, ps htt
 
 // List handles listing all stock lots
 func (h *StockLotHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
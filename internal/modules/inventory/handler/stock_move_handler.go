@@ -17,7 +17,7 @@ type StockMoveHandler struct {
 }
 
 // NewStockMoveHandler creates a new StockMoveHandler
-type NewStockMoveHandler(service *service.StockMoveService) *StockMoveHandler {
+func NewStockMoveHandler(service *service.StockMoveService) *StockMoveHandler {
 	return &StockMoveHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *StockMoveHandler) GetByID(w http.ResponseWriter, r *http.Request, ps ht
 
 // List handles listing all stock moves
 func (h *StockMoveHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
@@ -17,7 +17,7 @@ type StockPackageHandler struct {
 }
 
 // NewStockPackageHandler creates a new StockPackageHandler
-type NewStockPackageHandler(service *service.StockPackageService) *StockPackageHandler {
+func NewStockPackageHandler(service *service.StockPackageService) *StockPackageHandler {
 	return &StockPackageHandler{
 		service: service,
 	}
@@ -45,10 +45,6 @@ func (h *StockPackageHandler) Create(w http.ResponseWriter, r *http.Request, _ h
 		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
 
 	packageModel, err := h.service.Create(r.Context(), orgID, req)
 	if err != nil {
@@ -84,9 +80,9 @@ func (h *StockPackageHandler) GetByID(w http.ResponseWriter, r *http.Request, ps
 
 // List handles listing all stock packages
 func (h *StockPackageHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
@@ -17,7 +17,7 @@ type StockPickingHandler struct {
 }
 
 // NewStockPickingHandler creates a new StockPickingHandler
-type NewStockPickingHandler(service *service.StockPickingService) *StockPickingHandler {
+func NewStockPickingHandler(service *service.StockPickingService) *StockPickingHandler {
 	return &StockPickingHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *StockPickingHandler) GetByID(w http.ResponseWriter, r *http.Request, ps
 
 // List handles listing all stock pickings
 func (h *StockPickingHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
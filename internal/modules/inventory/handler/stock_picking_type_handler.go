@@ -17,7 +17,7 @@ type StockPickingTypeHandler struct {
 }
 
 // NewStockPickingTypeHandler creates a new StockPickingTypeHandler
-type NewStockPickingTypeHandler(service *service.StockPickingTypeService) *StockPickingTypeHandler {
+func NewStockPickingTypeHandler(service *service.StockPickingTypeService) *StockPickingTypeHandler {
 	return &StockPickingTypeHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *StockPickingTypeHandler) GetByID(w http.ResponseWriter, r *http.Request
 
 // List handles listing all stock picking types
 func (h *StockPickingTypeHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
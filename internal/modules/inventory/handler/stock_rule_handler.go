@@ -17,7 +17,7 @@ type StockRuleHandler struct {
 }
 
 // NewStockRuleHandler creates a new StockRuleHandler
-type NewStockRuleHandler(service *service.StockRuleService) *StockRuleHandler {
+func NewStockRuleHandler(service *service.StockRuleService) *StockRuleHandler {
 	return &StockRuleHandler{
 		service: service,
 	}
@@ -80,9 +80,9 @@ func (h *StockRuleHandler) GetByID(w http.ResponseWriter, r *http.Request, ps ht
 
 // List handles listing all stock rules
 func (h *StockRuleHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	orgID, err := auth.GetOrganizationIDFromContext(r.Context())
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	orgID, ok := middleware.GetOrganizationIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Organization not found in context", http.StatusUnauthorized)
 		return
 	}
 
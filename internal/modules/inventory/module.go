@@ -9,8 +9,10 @@ import (
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/service"
 	productsRepo "github.com/KevTiv/alieze-erp/internal/modules/products/repository"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
 	"github.com/KevTiv/alieze-erp/pkg/registry"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -30,7 +32,10 @@ type InventoryModule struct {
 	stockPickingTypeHandler *handler.StockPickingTypeHandler
 	stockPickingHandler     *handler.StockPickingHandler
 	stockMoveHandler        *handler.StockMoveHandler
+	stockAdjustmentHandler  *handler.StockAdjustmentHandler
+	consignmentHandler      *handler.ConsignmentHandler
 	integrationService      *service.InventoryIntegrationService
+	qualityControlService   *service.QualityControlService
 	logger                 *slog.Logger
 }
 
@@ -54,7 +59,7 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	warehouseRepo := repository.NewWarehouseRepository(deps.DB)
 	locationRepo := repository.NewStockLocationRepository(deps.DB)
 	quantRepo := repository.NewStockQuantRepository(deps.DB)
-	moveRepo := repository.NewStockMoveRepository(deps.DB)
+	moveRepo := repository.NewStockMoveRepository(deps.DB, m.logger)
 	analyticsRepo := repository.NewAnalyticsRepository(deps.DB)
 	barcodeRepo := repository.NewBarcodeRepository(deps.DB)
 	cycleCountRepo := repository.NewCycleCountRepository(deps.DB)
@@ -73,13 +78,16 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	qcAlertRepo := repository.NewQualityControlAlertRepository(deps.DB)
 
 	// New Inventory Repositories
-	stockPackageRepo := repository.NewStockPackageRepository(deps.DB, m.logger)
-	stockLotRepo := repository.NewStockLotRepository(deps.DB, m.logger)
-	procurementGroupRepo := repository.NewProcurementGroupRepository(deps.DB, m.logger)
-	stockRuleRepo := repository.NewStockRuleRepository(deps.DB, m.logger)
-	stockPickingTypeRepo := repository.NewStockPickingTypeRepository(deps.DB, m.logger)
+	dbx := sqlx.NewDb(deps.DB, "postgres")
+	stockPackageRepo := repository.NewStockPackageRepository(dbx, m.logger)
+	stockLotRepo := repository.NewStockLotRepository(dbx, m.logger)
+	procurementGroupRepo := repository.NewProcurementGroupRepository(dbx, m.logger)
+	stockRuleRepo := repository.NewStockRuleRepository(dbx, m.logger)
+	stockPickingTypeRepo := repository.NewStockPickingTypeRepository(dbx, m.logger)
 	stockPickingRepo := repository.NewStockPickingRepository(deps.DB, m.logger)
 	stockMoveRepo := repository.NewStockMoveRepository(deps.DB, m.logger)
+	stockAdjustmentRepo := repository.NewStockAdjustmentRepository(deps.DB)
+	consignmentRepo := repository.NewConsignmentRepository(deps.DB)
 
 	// Get products repository from dependencies
 	productsRepo, ok := deps.ProductRepo.(productsRepo.ProductRepo)
@@ -89,14 +97,15 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	}
 
 	// Create services
-	inventoryService := service.NewInventoryService(warehouseRepo, locationRepo, quantRepo, moveRepo)
-	analyticsService := service.NewAnalyticsService(analyticsRepo)
+	inventoryService := service.NewInventoryService(deps.DB, m.logger, warehouseRepo, locationRepo, quantRepo, moveRepo)
+	// Dashboard analytics are served from a stale-while-revalidate cache
+	analyticsService := service.NewCachedAnalyticsService(service.NewAnalyticsService(analyticsRepo), cache.Options{}, m.logger)
 	barcodeService := service.NewBarcodeService(barcodeRepo)
 	cycleCountService := service.NewCycleCountService(cycleCountRepo)
 	replenishmentService := service.NewReplenishmentService(replenishmentRuleRepo, replenishmentOrderRepo, inventoryService, productsRepo)
 	batchOperationService := service.NewBatchOperationService(batchOperationRepo, batchOperationItemRepo, inventoryService, productsRepo)
-	qualityControlService := service.NewQualityControlService(
-		qcInspectionRepo, qcChecklistRepo, qcChecklistItemRepo, qcInspectionItemRepo, qcAlertRepo, inventoryService,
+	m.qualityControlService = service.NewQualityControlService(
+		qcInspectionRepo, qcChecklistRepo, qcChecklistItemRepo, qcInspectionItemRepo, qcAlertRepo, inventoryService, productsRepo,
 	)
 
 	// New Inventory Services
@@ -107,6 +116,8 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	stockPickingTypeService := service.NewStockPickingTypeService(stockPickingTypeRepo)
 	stockPickingService := service.NewStockPickingService(stockPickingRepo)
 	stockMoveService := service.NewStockMoveService(stockMoveRepo)
+	stockAdjustmentService := service.NewStockAdjustmentService(stockAdjustmentRepo)
+	consignmentService := service.NewConsignmentService(consignmentRepo)
 
 	// Create integration service for other modules
 	m.integrationService = service.NewInventoryIntegrationService(stockMoveService, stockPickingService)
@@ -116,9 +127,15 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	m.analyticsHandler = handler.NewAnalyticsHandler(analyticsService)
 	m.barcodeHandler = handler.NewBarcodeHandler(barcodeService)
 	m.cycleCountHandler = handler.NewCycleCountHandler(cycleCountService)
-	m.replenishmentHandler = handler.NewReplenishmentHandler(replenishmentService)
+	// Replenishment and quality control read the caller from the context
+	// their auth service sets up
+	authService, ok := deps.AuthService.(handler.AuthService)
+	if !ok {
+		authService = handler.NewContextAuthService()
+	}
+	m.replenishmentHandler = handler.NewReplenishmentHandler(replenishmentService, authService)
 	m.batchOperationHandler = handler.NewBatchOperationHandler(batchOperationService)
-	m.qualityControlHandler = handler.NewQualityControlHandler(qualityControlService, deps.AuthService)
+	m.qualityControlHandler = handler.NewQualityControlHandler(m.qualityControlService, authService)
 
 	// New Inventory Handlers
 	m.stockPackageHandler = handler.NewStockPackageHandler(stockPackageService)
@@ -128,6 +145,8 @@ func (m *InventoryModule) Init(ctx context.Context, deps registry.Dependencies)
 	m.stockPickingTypeHandler = handler.NewStockPickingTypeHandler(stockPickingTypeService)
 	m.stockPickingHandler = handler.NewStockPickingHandler(stockPickingService)
 	m.stockMoveHandler = handler.NewStockMoveHandler(stockMoveService)
+	m.stockAdjustmentHandler = handler.NewStockAdjustmentHandler(stockAdjustmentService)
+	m.consignmentHandler = handler.NewConsignmentHandler(consignmentService)
 
 	m.logger.Info("Inventory module initialized successfully")
 	return nil
@@ -179,6 +198,12 @@ func (m *InventoryModule) RegisterRoutes(router interface{}) {
 			if m.stockMoveHandler != nil {
 				m.stockMoveHandler.RegisterRoutes(r)
 			}
+			if m.stockAdjustmentHandler != nil {
+				m.stockAdjustmentHandler.RegisterRoutes(r)
+			}
+			if m.consignmentHandler != nil {
+				m.consignmentHandler.RegisterRoutes(r)
+			}
 		}
 	}
 }
@@ -198,3 +223,19 @@ func (m *InventoryModule) Health() error {
 func (m *InventoryModule) GetIntegrationService() *service.InventoryIntegrationService {
 	return m.integrationService
 }
+
+// SetInspectorQualifier only lets QC inspections be assigned to users holding
+// the required certifications. It must be called after Init.
+func (m *InventoryModule) SetInspectorQualifier(qualifier service.InspectorQualifier) {
+	if m.qualityControlService != nil {
+		m.qualityControlService.SetInspectorQualifier(qualifier)
+	}
+}
+
+// SetInstrumentChecker only lets QC inspection items record calibrated
+// measuring equipment. It must be called after Init.
+func (m *InventoryModule) SetInstrumentChecker(checker service.InstrumentChecker) {
+	if m.qualityControlService != nil {
+		m.qualityControlService.SetInstrumentChecker(checker)
+	}
+}
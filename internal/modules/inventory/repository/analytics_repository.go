@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strconv"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
 
@@ -71,19 +72,19 @@ func (r *analyticsRepository) GetInventoryValuation(ctx context.Context, orgID u
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
 	// Apply sorting and pagination
 	query += ` ORDER BY current_value DESC`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -195,19 +196,19 @@ func (r *analyticsRepository) GetInventoryTurnover(ctx context.Context, orgID uu
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
 	// Apply sorting and pagination
 	query += ` ORDER BY turnover_ratio DESC`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -282,24 +283,24 @@ func (r *analyticsRepository) GetInventoryAging(ctx context.Context, orgID uuid.
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
 	if len(request.LocationIDs) > 0 {
-		query += ` AND location_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND location_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.LocationIDs)
 	}
 
 	// Apply sorting and pagination
 	query += ` ORDER BY value DESC`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -385,19 +386,19 @@ func (r *analyticsRepository) GetDeadStock(ctx context.Context, orgID uuid.UUID,
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
 	// Apply sorting and pagination
 	query += ` ORDER BY total_value DESC`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -476,35 +477,35 @@ func (r *analyticsRepository) GetMovementSummary(ctx context.Context, orgID uuid
 
 	// Apply date filters
 	if request.DateFrom != nil {
-		query += ` AND month >= $` + string(len(params)+1)
+		query += ` AND month >= $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.DateFrom)
 	}
 
 	if request.DateTo != nil {
-		query += ` AND month <= $` + string(len(params)+1)
+		query += ` AND month <= $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.DateTo)
 	}
 
 	// Apply product/category filters
 	if len(request.ProductIDs) > 0 {
-		query += ` AND product_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND product_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.ProductIDs)
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
 	// Apply sorting and pagination
 	query += ` ORDER BY month DESC, total_value DESC`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -553,7 +554,7 @@ func (r *analyticsRepository) GetReorderAnalysis(ctx context.Context, orgID uuid
 	}
 
 	if len(request.CategoryIDs) > 0 {
-		query += ` AND category_id = ANY($` + string(len(params)+1) + `)`
+		query += ` AND category_id = ANY($` + strconv.Itoa(len(params)+1) + `)`
 		params = append(params, request.CategoryIDs)
 	}
 
@@ -566,12 +567,12 @@ func (r *analyticsRepository) GetReorderAnalysis(ctx context.Context, orgID uuid
 		END,
 		days_until_reorder`
 	if request.Limit != nil {
-		query += ` LIMIT $` + string(len(params)+1)
+		query += ` LIMIT $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Limit)
 	}
 
 	if request.Offset != nil {
-		query += ` OFFSET $` + string(len(params)+1)
+		query += ` OFFSET $` + strconv.Itoa(len(params)+1)
 		params = append(params, *request.Offset)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
@@ -255,11 +256,11 @@ func (r *barcodeRepository) ListScanningSessions(ctx context.Context, orgID uuid
 	params = append(params, orgID)
 
 	if status != nil {
-		query += ` AND status = $` + string(len(params)+1)
+		query += ` AND status = $` + strconv.Itoa(len(params)+1)
 		params = append(params, *status)
 	}
 
-	query += ` ORDER BY start_time DESC LIMIT $` + string(len(params)+1) + ` OFFSET $` + string(len(params)+2)
+	query += ` ORDER BY start_time DESC LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
 	params = append(params, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, params...)
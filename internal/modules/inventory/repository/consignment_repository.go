@@ -0,0 +1,628 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrInsufficientConsignedStock is returned when consuming more consigned
+	// stock than the vendor has at the location
+	ErrInsufficientConsignedStock = errors.New("insufficient consigned stock")
+
+	// ErrNoPurchaseJournal is returned when billing consumed consignment
+	// stock for an organization without a purchase journal and its default account
+	ErrNoPurchaseJournal = errors.New("no purchase journal with a default account")
+)
+
+// ConsignmentRepository interface for vendor-owned consignment stock
+type ConsignmentRepository interface {
+	// Agreement operations
+	SaveAgreement(ctx context.Context, orgID uuid.UUID, request types.ConsignmentAgreementRequest) (*types.ConsignmentAgreement, error)
+	FindAgreement(ctx context.Context, orgID, id uuid.UUID) (*types.ConsignmentAgreement, error)
+	FindAgreementByVendor(ctx context.Context, orgID, vendorID uuid.UUID) (*types.ConsignmentAgreement, error)
+	ListAgreements(ctx context.Context, orgID uuid.UUID) ([]types.ConsignmentAgreement, error)
+
+	// Stock operations
+	Receive(ctx context.Context, orgID, userID uuid.UUID, request types.ConsignmentReceiptRequest) (*types.ConsignmentReceipt, error)
+	Consume(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, unitPrice float64, request types.ConsignmentConsumptionRequest) (*types.ConsignmentConsumption, error)
+	ListOwnership(ctx context.Context, filter types.StockOwnershipFilter) ([]types.StockOwnership, error)
+
+	// Consumption and billing operations
+	ListConsumptions(ctx context.Context, filter types.ConsignmentConsumptionFilter) ([]types.ConsignmentConsumption, error)
+	Bill(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, consumptionIDs []uuid.UUID) (*types.ConsignmentBill, error)
+	StatementLines(ctx context.Context, orgID uuid.UUID, agreement types.ConsignmentAgreement, from, to time.Time) ([]types.ConsignmentStatementLine, error)
+}
+
+type consignmentRepository struct {
+	db *sql.DB
+}
+
+func NewConsignmentRepository(db *sql.DB) ConsignmentRepository {
+	return &consignmentRepository{db: db}
+}
+
+// SaveAgreement creates the agreement with a vendor or replaces it, items
+// included. It returns nil when the vendor is not one of the organization's contacts.
+func (r *consignmentRepository) SaveAgreement(ctx context.Context, orgID uuid.UUID, request types.ConsignmentAgreementRequest) (*types.ConsignmentAgreement, error) {
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO inventory_consignment_agreements (organization_id, vendor_id, reference, billing_policy, active)
+		SELECT $1, c.id, NULLIF($3, ''), $4, $5
+		FROM contacts c
+		WHERE c.id = $2 AND c.organization_id = $1 AND c.deleted_at IS NULL
+		ON CONFLICT (organization_id, vendor_id) DO UPDATE SET
+			reference = EXCLUDED.reference,
+			billing_policy = EXCLUDED.billing_policy,
+			active = EXCLUDED.active,
+			updated_at = NOW()
+		RETURNING id
+	`, orgID, request.VendorID, request.Reference, request.BillingPolicy, active).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save consignment agreement: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inventory_consignment_items WHERE agreement_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to replace consignment items: %w", err)
+	}
+	for _, item := range request.Items {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO inventory_consignment_items (agreement_id, product_id, unit_price)
+			SELECT $1, p.id, $3
+			FROM products p
+			WHERE p.id = $2 AND p.organization_id = $4 AND p.deleted_at IS NULL
+		`, id, item.ProductID, item.UnitPrice, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save consignment item: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit consignment agreement: %w", err)
+	}
+
+	return r.FindAgreement(ctx, orgID, id)
+}
+
+const agreementQuery = `
+	SELECT a.id, a.organization_id, a.vendor_id, COALESCE(c.name, ''), COALESCE(a.reference, ''),
+		a.billing_policy, a.active, a.created_at, a.updated_at
+	FROM inventory_consignment_agreements a
+	LEFT JOIN contacts c ON c.id = a.vendor_id
+	WHERE a.organization_id = $1
+`
+
+// FindAgreement retrieves an agreement with its items, or nil when it does not exist
+func (r *consignmentRepository) FindAgreement(ctx context.Context, orgID, id uuid.UUID) (*types.ConsignmentAgreement, error) {
+	return r.findAgreement(ctx, agreementQuery+` AND a.id = $2`, orgID, id)
+}
+
+// FindAgreementByVendor retrieves the agreement with a vendor, or nil when there is none
+func (r *consignmentRepository) FindAgreementByVendor(ctx context.Context, orgID, vendorID uuid.UUID) (*types.ConsignmentAgreement, error) {
+	return r.findAgreement(ctx, agreementQuery+` AND a.vendor_id = $2`, orgID, vendorID)
+}
+
+func (r *consignmentRepository) findAgreement(ctx context.Context, query string, orgID, id uuid.UUID) (*types.ConsignmentAgreement, error) {
+	agreements, err := r.listAgreements(ctx, query, orgID, id)
+	if err != nil || len(agreements) == 0 {
+		return nil, err
+	}
+	return &agreements[0], nil
+}
+
+// ListAgreements retrieves the organization's agreements with their items
+func (r *consignmentRepository) ListAgreements(ctx context.Context, orgID uuid.UUID) ([]types.ConsignmentAgreement, error) {
+	return r.listAgreements(ctx, agreementQuery+` ORDER BY c.name, a.id`, orgID)
+}
+
+func (r *consignmentRepository) listAgreements(ctx context.Context, query string, orgID uuid.UUID, args ...interface{}) ([]types.ConsignmentAgreement, error) {
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{orgID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consignment agreements: %w", err)
+	}
+	defer rows.Close()
+
+	var agreements []types.ConsignmentAgreement
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var a types.ConsignmentAgreement
+		if err := rows.Scan(
+			&a.ID, &a.OrganizationID, &a.VendorID, &a.VendorName, &a.Reference,
+			&a.BillingPolicy, &a.Active, &a.CreatedAt, &a.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment agreement: %w", err)
+		}
+		a.Items = []types.ConsignmentItem{}
+		index[a.ID] = len(agreements)
+		agreements = append(agreements, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(agreements) == 0 {
+		return agreements, nil
+	}
+
+	ids := make([]string, 0, len(agreements))
+	for _, a := range agreements {
+		ids = append(ids, a.ID.String())
+	}
+	itemRows, err := r.db.QueryContext(ctx, `
+		SELECT i.agreement_id, i.product_id, COALESCE(p.name, ''), i.unit_price
+		FROM inventory_consignment_items i
+		LEFT JOIN products p ON p.id = i.product_id
+		WHERE i.agreement_id = ANY($1::uuid[])
+		ORDER BY p.name, i.product_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consignment items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var agreementID uuid.UUID
+		var item types.ConsignmentItem
+		if err := itemRows.Scan(&agreementID, &item.ProductID, &item.ProductName, &item.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment item: %w", err)
+		}
+		i := index[agreementID]
+		agreements[i].Items = append(agreements[i].Items, item)
+	}
+	return agreements, itemRows.Err()
+}
+
+// Receive books consigned stock from the vendor's location into an internal
+// location, owned by the vendor. It returns nil when the product, location
+// or lot does not exist.
+func (r *consignmentRepository) Receive(ctx context.Context, orgID, userID uuid.UUID, request types.ConsignmentReceiptRequest) (*types.ConsignmentReceipt, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var productName string
+	err = tx.QueryRowContext(ctx, `
+		SELECT p.name
+		FROM products p
+		JOIN stock_locations l ON l.id = $3 AND l.organization_id = $1 AND l.usage = 'internal' AND l.deleted_at IS NULL
+		WHERE p.id = $2 AND p.organization_id = $1 AND p.deleted_at IS NULL
+			AND ($4::uuid IS NULL OR EXISTS (
+				SELECT 1 FROM stock_lots lot WHERE lot.id = $4 AND lot.product_id = p.id AND lot.organization_id = $1
+			))
+	`, orgID, request.ProductID, request.LocationID, request.LotID).Scan(&productName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check consignment receipt: %w", err)
+	}
+
+	vendorLocationID, err := virtualLocation(ctx, tx, orgID, "supplier", "Partner Locations/Vendors")
+	if err != nil {
+		return nil, err
+	}
+
+	var lotIDs interface{}
+	if request.LotID != nil {
+		lotIDs = pq.Array([]string{request.LotID.String()})
+	}
+	receipt := types.ConsignmentReceipt{
+		VendorID:   request.VendorID,
+		ProductID:  request.ProductID,
+		LocationID: request.LocationID,
+		LotID:      request.LotID,
+		Quantity:   request.Quantity,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_moves (
+			organization_id, name, product_id, product_uom_qty, location_id, location_dest_id,
+			partner_id, state, origin, reference, lot_ids, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, 'done', 'consignment_receipt', NULLIF($8, ''), $9::uuid[], $10)
+		RETURNING id
+	`, orgID, "Consignment receipt: "+productName, request.ProductID, request.Quantity,
+		vendorLocationID, request.LocationID, request.VendorID, request.Reference, lotIDs, userID,
+	).Scan(&receipt.StockMoveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consignment receipt move: %w", err)
+	}
+
+	if err := adjustQuant(ctx, tx, orgID, request.ProductID, request.LocationID, request.LotID, &request.VendorID, request.Quantity); err != nil {
+		return nil, err
+	}
+	if err := consignedQuantity(ctx, tx, orgID, request.VendorID, request.ProductID, request.LocationID, request.LotID, false).Scan(&receipt.Consigned); err != nil {
+		return nil, fmt.Errorf("failed to get consigned quantity: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit consignment receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// consignedQuantity selects the quantity of a vendor's package-less quant,
+// locking it when forUpdate is set
+func consignedQuantity(ctx context.Context, tx *sql.Tx, orgID, vendorID, productID, locationID uuid.UUID, lotID *uuid.UUID, forUpdate bool) *sql.Row {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0) FROM (
+			SELECT quantity FROM stock_quants
+			WHERE organization_id = $1 AND owner_id = $2 AND product_id = $3 AND location_id = $4
+				AND lot_id IS NOT DISTINCT FROM $5 AND package_id IS NULL
+	`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	query += `) q`
+	return tx.QueryRowContext(ctx, query, orgID, vendorID, productID, locationID, lotID)
+}
+
+// Consume transfers consigned stock from the vendor to the organization at
+// the agreement's price and records the consumption
+func (r *consignmentRepository) Consume(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, unitPrice float64, request types.ConsignmentConsumptionRequest) (*types.ConsignmentConsumption, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var consigned float64
+	if err := consignedQuantity(ctx, tx, orgID, agreement.VendorID, request.ProductID, request.LocationID, request.LotID, true).Scan(&consigned); err != nil {
+		return nil, fmt.Errorf("failed to lock consigned stock: %w", err)
+	}
+	if consigned < request.Quantity {
+		return nil, fmt.Errorf("%w: %g consigned at the location", ErrInsufficientConsignedStock, consigned)
+	}
+
+	if err := adjustQuant(ctx, tx, orgID, request.ProductID, request.LocationID, request.LotID, &agreement.VendorID, -request.Quantity); err != nil {
+		return nil, err
+	}
+	if err := adjustQuant(ctx, tx, orgID, request.ProductID, request.LocationID, request.LotID, nil, request.Quantity); err != nil {
+		return nil, err
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO inventory_consignment_consumptions (
+			organization_id, agreement_id, vendor_id, product_id, location_id, lot_id,
+			quantity, unit_price, amount, reference, consumed_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, ROUND(($7 * $8)::numeric, 2), NULLIF($9, ''), $10)
+		RETURNING id
+	`, orgID, agreement.ID, agreement.VendorID, request.ProductID, request.LocationID, request.LotID,
+		request.Quantity, unitPrice, request.Reference, userID,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record consignment consumption: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit consignment consumption: %w", err)
+	}
+
+	consumptions, err := r.listConsumptions(ctx, consumptionQuery+` AND c.id = $2`, orgID, id)
+	if err != nil || len(consumptions) == 0 {
+		return nil, err
+	}
+	return &consumptions[0], nil
+}
+
+// ListOwnership retrieves on-hand stock at internal locations by product,
+// location, lot and owner
+func (r *consignmentRepository) ListOwnership(ctx context.Context, filter types.StockOwnershipFilter) ([]types.StockOwnership, error) {
+	query := `
+		SELECT q.product_id, COALESCE(p.name, ''), q.location_id, COALESCE(l.complete_name, l.name, ''),
+			q.lot_id, COALESCE(lot.name, ''), q.owner_id, COALESCE(c.name, ''), SUM(q.quantity)
+		FROM stock_quants q
+		JOIN stock_locations l ON l.id = q.location_id AND l.usage = 'internal'
+		LEFT JOIN products p ON p.id = q.product_id
+		LEFT JOIN stock_lots lot ON lot.id = q.lot_id
+		LEFT JOIN contacts c ON c.id = q.owner_id
+		WHERE q.organization_id = $1
+	`
+	args := []interface{}{filter.OrganizationID}
+	if filter.ProductID != nil {
+		args = append(args, *filter.ProductID)
+		query += fmt.Sprintf(" AND q.product_id = $%d", len(args))
+	}
+	if filter.LocationID != nil {
+		args = append(args, *filter.LocationID)
+		query += fmt.Sprintf(" AND q.location_id = $%d", len(args))
+	}
+	if filter.OwnerID != nil {
+		args = append(args, *filter.OwnerID)
+		query += fmt.Sprintf(" AND q.owner_id = $%d", len(args))
+	}
+	if filter.ConsignedOnly {
+		query += ` AND q.owner_id IS NOT NULL`
+	}
+	query += `
+		GROUP BY 1, 2, 3, 4, 5, 6, 7, 8
+		HAVING SUM(q.quantity) <> 0
+		ORDER BY 2, 4, 6, 8
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock ownership: %w", err)
+	}
+	defer rows.Close()
+
+	var stock []types.StockOwnership
+	for rows.Next() {
+		var s types.StockOwnership
+		var lotID, ownerID uuid.NullUUID
+		if err := rows.Scan(
+			&s.ProductID, &s.ProductName, &s.LocationID, &s.LocationName,
+			&lotID, &s.LotName, &ownerID, &s.OwnerName, &s.Quantity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan stock ownership: %w", err)
+		}
+		if lotID.Valid {
+			s.LotID = &lotID.UUID
+		}
+		if ownerID.Valid {
+			s.OwnerID = &ownerID.UUID
+			s.Consigned = true
+		}
+		stock = append(stock, s)
+	}
+	return stock, rows.Err()
+}
+
+const consumptionQuery = `
+	SELECT c.id, c.organization_id, c.agreement_id, c.vendor_id, c.product_id, COALESCE(p.name, ''),
+		c.location_id, c.lot_id, c.quantity, c.unit_price, c.amount, COALESCE(c.reference, ''),
+		c.consumed_by, c.consumed_at, c.invoice_id, c.billed_at
+	FROM inventory_consignment_consumptions c
+	LEFT JOIN products p ON p.id = c.product_id
+	WHERE c.organization_id = $1
+`
+
+// ListConsumptions retrieves consumptions, most recent first
+func (r *consignmentRepository) ListConsumptions(ctx context.Context, filter types.ConsignmentConsumptionFilter) ([]types.ConsignmentConsumption, error) {
+	query := consumptionQuery
+	var args []interface{}
+	if filter.AgreementID != nil {
+		args = append(args, *filter.AgreementID)
+		query += fmt.Sprintf(" AND c.agreement_id = $%d", len(args)+1)
+	}
+	if filter.Unbilled {
+		query += ` AND c.invoice_id IS NULL`
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(" AND c.consumed_at >= $%d", len(args)+1)
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(" AND c.consumed_at < $%d", len(args)+1)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY c.consumed_at DESC, c.id LIMIT $%d OFFSET $%d", len(args), len(args)+1)
+
+	return r.listConsumptions(ctx, query, filter.OrganizationID, args...)
+}
+
+func (r *consignmentRepository) listConsumptions(ctx context.Context, query string, orgID uuid.UUID, args ...interface{}) ([]types.ConsignmentConsumption, error) {
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{orgID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consignment consumptions: %w", err)
+	}
+	defer rows.Close()
+
+	var consumptions []types.ConsignmentConsumption
+	for rows.Next() {
+		var c types.ConsignmentConsumption
+		var lotID, invoiceID uuid.NullUUID
+		var billedAt sql.NullTime
+		if err := rows.Scan(
+			&c.ID, &c.OrganizationID, &c.AgreementID, &c.VendorID, &c.ProductID, &c.ProductName,
+			&c.LocationID, &lotID, &c.Quantity, &c.UnitPrice, &c.Amount, &c.Reference,
+			&c.ConsumedBy, &c.ConsumedAt, &invoiceID, &billedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment consumption: %w", err)
+		}
+		if lotID.Valid {
+			c.LotID = &lotID.UUID
+		}
+		if invoiceID.Valid {
+			c.InvoiceID = &invoiceID.UUID
+		}
+		if billedAt.Valid {
+			c.BilledAt = &billedAt.Time
+		}
+		consumptions = append(consumptions, c)
+	}
+	return consumptions, rows.Err()
+}
+
+// Bill drafts a vendor bill for unbilled consumptions of an agreement, all
+// of them when consumptionIDs is empty. It returns nil when there is nothing
+// to bill.
+func (r *consignmentRepository) Bill(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, consumptionIDs []uuid.UUID) (*types.ConsignmentBill, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT c.id, c.product_id, COALESCE(p.name, ''), c.quantity, c.unit_price, c.amount
+		FROM inventory_consignment_consumptions c
+		LEFT JOIN products p ON p.id = c.product_id
+		WHERE c.organization_id = $1 AND c.agreement_id = $2 AND c.invoice_id IS NULL
+	`
+	args := []interface{}{orgID, agreement.ID}
+	if len(consumptionIDs) > 0 {
+		ids := make([]string, 0, len(consumptionIDs))
+		for _, id := range consumptionIDs {
+			ids = append(ids, id.String())
+		}
+		args = append(args, pq.Array(ids))
+		query += ` AND c.id = ANY($3::uuid[])`
+	}
+	query += ` ORDER BY c.consumed_at, c.id FOR UPDATE OF c`
+
+	type billLine struct {
+		consumptionID, productID uuid.UUID
+		name                     string
+		quantity, price, amount  float64
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock consignment consumptions: %w", err)
+	}
+	var lines []billLine
+	var total float64
+	for rows.Next() {
+		var l billLine
+		if err := rows.Scan(&l.consumptionID, &l.productID, &l.name, &l.quantity, &l.price, &l.amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan consignment consumption: %w", err)
+		}
+		total += l.amount
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	var journalID, accountID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, default_account_id
+		FROM account_journals
+		WHERE organization_id = $1 AND type = 'purchase' AND active = true AND default_account_id IS NOT NULL
+		ORDER BY created_at
+		LIMIT 1
+	`, orgID).Scan(&journalID, &accountID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPurchaseJournal
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find purchase journal: %w", err)
+	}
+
+	bill := types.ConsignmentBill{
+		AgreementID:  agreement.ID,
+		VendorID:     agreement.VendorID,
+		Consumptions: len(lines),
+		AmountTotal:  total,
+	}
+	origin := agreement.Reference
+	if origin == "" {
+		origin = "Consignment"
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO invoices (
+			organization_id, move_type, invoice_date, state, partner_id, journal_id,
+			amount_untaxed, amount_total, amount_residual, amount_untaxed_signed, amount_total_signed,
+			invoice_origin, user_id, created_by
+		) VALUES ($1, 'in_invoice', CURRENT_DATE, 'draft', $2, $3, $4, $4, $4, -$4, -$4, $5, $6, $6)
+		RETURNING id
+	`, orgID, agreement.VendorID, journalID, total, origin, userID).Scan(&bill.InvoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vendor bill: %w", err)
+	}
+
+	for i, l := range lines {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO invoice_lines (
+				organization_id, move_id, sequence, name, account_id, product_id, quantity, price_unit,
+				debit, balance, price_subtotal, price_total, partner_id, display_type, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9, $9, $9, $10, 'product', $11)
+		`, orgID, bill.InvoiceID, (i+1)*10, "Consigned: "+l.name, accountID, l.productID,
+			l.quantity, l.price, l.amount, agreement.VendorID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create vendor bill line: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE inventory_consignment_consumptions SET invoice_id = $2, billed_at = NOW() WHERE id = $1
+		`, l.consumptionID, bill.InvoiceID); err != nil {
+			return nil, fmt.Errorf("failed to mark consumption billed: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit vendor bill: %w", err)
+	}
+	return &bill, nil
+}
+
+// StatementLines totals, per product of an agreement, the stock received and
+// consumed in a date range and the vendor's stock on hand now
+func (r *consignmentRepository) StatementLines(ctx context.Context, orgID uuid.UUID, agreement types.ConsignmentAgreement, from, to time.Time) ([]types.ConsignmentStatementLine, error) {
+	query := `
+		WITH received AS (
+			SELECT product_id, SUM(product_uom_qty) AS quantity
+			FROM stock_moves
+			WHERE organization_id = $1 AND partner_id = $3 AND origin = 'consignment_receipt'
+				AND state = 'done' AND deleted_at IS NULL AND date >= $4 AND date < $5
+			GROUP BY product_id
+		), consumed AS (
+			SELECT product_id, SUM(quantity) AS quantity, SUM(amount) AS amount,
+				SUM(amount) FILTER (WHERE invoice_id IS NOT NULL) AS billed
+			FROM inventory_consignment_consumptions
+			WHERE organization_id = $1 AND agreement_id = $2 AND consumed_at >= $4 AND consumed_at < $5
+			GROUP BY product_id
+		), on_hand AS (
+			SELECT q.product_id, SUM(q.quantity) AS quantity
+			FROM stock_quants q
+			JOIN stock_locations l ON l.id = q.location_id AND l.usage = 'internal'
+			WHERE q.organization_id = $1 AND q.owner_id = $3
+			GROUP BY q.product_id
+		), products_seen AS (
+			SELECT product_id FROM received
+			UNION SELECT product_id FROM consumed
+			UNION SELECT product_id FROM on_hand
+		)
+		SELECT s.product_id, COALESCE(p.name, ''),
+			COALESCE(received.quantity, 0), COALESCE(consumed.quantity, 0),
+			COALESCE(consumed.amount, 0), COALESCE(consumed.billed, 0), COALESCE(on_hand.quantity, 0)
+		FROM products_seen s
+		LEFT JOIN products p ON p.id = s.product_id
+		LEFT JOIN received ON received.product_id = s.product_id
+		LEFT JOIN consumed ON consumed.product_id = s.product_id
+		LEFT JOIN on_hand ON on_hand.product_id = s.product_id
+		ORDER BY 2, 1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, agreement.ID, agreement.VendorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment statement: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []types.ConsignmentStatementLine{}
+	for rows.Next() {
+		var l types.ConsignmentStatementLine
+		if err := rows.Scan(
+			&l.ProductID, &l.ProductName, &l.ReceivedQuantity, &l.ConsumedQuantity,
+			&l.ConsumedAmount, &l.BilledAmount, &l.OnHandQuantity,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment statement line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
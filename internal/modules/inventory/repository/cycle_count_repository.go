@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strconv"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
 
@@ -127,11 +128,11 @@ func (r *cycleCountRepository) ListCycleCountPlans(ctx context.Context, orgID uu
 	params = append(params, orgID)
 
 	if status != nil {
-		query += ` AND status = $` + string(len(params)+1)
+		query += ` AND status = $` + strconv.Itoa(len(params)+1)
 		params = append(params, *status)
 	}
 
-	query += ` ORDER BY priority, created_at DESC LIMIT $` + string(len(params)+1) + ` OFFSET $` + string(len(params)+2)
+	query += ` ORDER BY priority, created_at DESC LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
 	params = append(params, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, params...)
@@ -251,11 +252,11 @@ func (r *cycleCountRepository) ListCycleCountSessions(ctx context.Context, orgID
 	params = append(params, orgID)
 
 	if status != nil {
-		query += ` AND status = $` + string(len(params)+1)
+		query += ` AND status = $` + strconv.Itoa(len(params)+1)
 		params = append(params, *status)
 	}
 
-	query += ` ORDER BY start_time DESC LIMIT $` + string(len(params)+1) + ` OFFSET $` + string(len(params)+2)
+	query += ` ORDER BY start_time DESC LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
 	params = append(params, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, params...)
@@ -564,11 +565,11 @@ func (r *cycleCountRepository) ListCycleCountAdjustments(ctx context.Context, or
 	params = append(params, orgID)
 
 	if status != nil {
-		query += ` AND status = $` + string(len(params)+1)
+		query += ` AND status = $` + strconv.Itoa(len(params)+1)
 		params = append(params, *status)
 	}
 
-	query += ` ORDER BY adjustment_time DESC LIMIT $` + string(len(params)+1) + ` OFFSET $` + string(len(params)+2)
+	query += ` ORDER BY adjustment_time DESC LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
 	params = append(params, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, params...)
@@ -799,11 +800,11 @@ func (r *cycleCountRepository) GetCycleCountAccuracyHistory(ctx context.Context,
 	params = append(params, orgID)
 
 	if productID != nil {
-		query += ` AND product_id = $` + string(len(params)+1)
+		query += ` AND product_id = $` + strconv.Itoa(len(params)+1)
 		params = append(params, *productID)
 	}
 
-	query += ` ORDER BY count_date DESC LIMIT $` + string(len(params)+1) + ` OFFSET $` + string(len(params)+2)
+	query += ` ORDER BY count_date DESC LIMIT $` + strconv.Itoa(len(params)+1) + ` OFFSET $` + strconv.Itoa(len(params)+2)
 	params = append(params, limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, params...)
@@ -22,9 +22,9 @@ func NewQualityControlInspectionItemRepository(db *sql.DB) QualityControlInspect
 func (r *qualityControlInspectionItemRepository) Create(ctx context.Context, item types.QualityControlInspectionItem) (*types.QualityControlInspectionItem, error) {
 	query := `
 		INSERT INTO quality_control_inspection_items
-		(id, inspection_id, checklist_item_id, description, result, notes, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, inspection_id, checklist_item_id, description, result, notes, created_at
+		(id, inspection_id, checklist_item_id, description, result, notes, equipment_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, inspection_id, checklist_item_id, description, result, notes, equipment_id, created_at
 	`
 
 	if item.ID == uuid.Nil {
@@ -39,9 +39,9 @@ func (r *qualityControlInspectionItemRepository) Create(ctx context.Context, ite
 
 	var created types.QualityControlInspectionItem
 	err := r.db.QueryRowContext(ctx, query,
-		item.ID, item.InspectionID, item.ChecklistItemID, item.Description, item.Result, item.Notes, item.CreatedAt,
+		item.ID, item.InspectionID, item.ChecklistItemID, item.Description, item.Result, item.Notes, item.EquipmentID, item.CreatedAt,
 	).Scan(
-		&created.ID, &created.InspectionID, &created.ChecklistItemID, &created.Description, &created.Result, &created.Notes, &created.CreatedAt,
+		&created.ID, &created.InspectionID, &created.ChecklistItemID, &created.Description, &created.Result, &created.Notes, &created.EquipmentID, &created.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create quality control inspection item: %w", err)
@@ -52,13 +52,13 @@ func (r *qualityControlInspectionItemRepository) Create(ctx context.Context, ite
 
 func (r *qualityControlInspectionItemRepository) FindByID(ctx context.Context, id uuid.UUID) (*types.QualityControlInspectionItem, error) {
 	query := `
-		SELECT id, inspection_id, checklist_item_id, description, result, notes, created_at
+		SELECT id, inspection_id, checklist_item_id, description, result, notes, equipment_id, created_at
 		FROM quality_control_inspection_items WHERE id = $1
 	`
 
 	var item types.QualityControlInspectionItem
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&item.ID, &item.InspectionID, &item.ChecklistItemID, &item.Description, &item.Result, &item.Notes, &item.CreatedAt,
+		&item.ID, &item.InspectionID, &item.ChecklistItemID, &item.Description, &item.Result, &item.Notes, &item.EquipmentID, &item.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -72,7 +72,7 @@ func (r *qualityControlInspectionItemRepository) FindByID(ctx context.Context, i
 
 func (r *qualityControlInspectionItemRepository) FindByInspection(ctx context.Context, inspectionID uuid.UUID) ([]types.QualityControlInspectionItem, error) {
 	query := `
-		SELECT id, inspection_id, checklist_item_id, description, result, notes, created_at
+		SELECT id, inspection_id, checklist_item_id, description, result, notes, equipment_id, created_at
 		FROM quality_control_inspection_items WHERE inspection_id = $1
 		ORDER BY created_at ASC
 	`
@@ -87,7 +87,7 @@ func (r *qualityControlInspectionItemRepository) FindByInspection(ctx context.Co
 	for rows.Next() {
 		var item types.QualityControlInspectionItem
 		err := rows.Scan(
-			&item.ID, &item.InspectionID, &item.ChecklistItemID, &item.Description, &item.Result, &item.Notes, &item.CreatedAt,
+			&item.ID, &item.InspectionID, &item.ChecklistItemID, &item.Description, &item.Result, &item.Notes, &item.EquipmentID, &item.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan quality control inspection item: %w", err)
@@ -102,16 +102,16 @@ func (r *qualityControlInspectionItemRepository) FindByInspection(ctx context.Co
 func (r *qualityControlInspectionItemRepository) Update(ctx context.Context, item types.QualityControlInspectionItem) (*types.QualityControlInspectionItem, error) {
 	query := `
 		UPDATE quality_control_inspection_items
-		SET description = $2, result = $3, notes = $4
+		SET description = $2, result = $3, notes = $4, equipment_id = $5
 		WHERE id = $1
-		RETURNING id, inspection_id, checklist_item_id, description, result, notes, created_at
+		RETURNING id, inspection_id, checklist_item_id, description, result, notes, equipment_id, created_at
 	`
 
 	var updated types.QualityControlInspectionItem
 	err := r.db.QueryRowContext(ctx, query,
-		item.ID, item.Description, item.Result, item.Notes,
+		item.ID, item.Description, item.Result, item.Notes, item.EquipmentID,
 	).Scan(
-		&updated.ID, &updated.InspectionID, &updated.ChecklistItemID, &updated.Description, &updated.Result, &updated.Notes, &updated.CreatedAt,
+		&updated.ID, &updated.InspectionID, &updated.ChecklistItemID, &updated.Description, &updated.Result, &updated.Notes, &updated.EquipmentID, &updated.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("quality control inspection item not found")
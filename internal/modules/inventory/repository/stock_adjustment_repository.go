@@ -0,0 +1,527 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrAdjustmentNotPending is returned when reviewing an adjustment that was
+// already applied or rejected
+var ErrAdjustmentNotPending = errors.New("stock adjustment is not pending")
+
+// StockAdjustmentRepository interface for manual stock adjustments
+type StockAdjustmentRepository interface {
+	// Reason code operations
+	ListReasons(ctx context.Context, orgID uuid.UUID) ([]types.AdjustmentReason, error)
+	FindReason(ctx context.Context, orgID uuid.UUID, code string) (*types.AdjustmentReason, error)
+	SaveReason(ctx context.Context, orgID uuid.UUID, request types.AdjustmentReasonRequest) (*types.AdjustmentReason, error)
+
+	// Approval settings operations
+	GetApprovalSettings(ctx context.Context, orgID uuid.UUID) (*types.AdjustmentApprovalSettings, error)
+	SaveApprovalSettings(ctx context.Context, orgID uuid.UUID, settings types.AdjustmentApprovalSettings) error
+
+	// Adjustment operations
+	FindStock(ctx context.Context, orgID, productID, locationID uuid.UUID) (*types.AdjustmentStock, error)
+	Create(ctx context.Context, adjustment types.StockAdjustment) (*types.StockAdjustment, error)
+	FindByID(ctx context.Context, orgID, id uuid.UUID) (*types.StockAdjustment, error)
+	List(ctx context.Context, filter types.StockAdjustmentFilter) ([]types.StockAdjustment, error)
+	Apply(ctx context.Context, orgID, id uuid.UUID, reviewedBy *uuid.UUID, note string) (*types.StockAdjustment, error)
+	Reject(ctx context.Context, orgID, id, reviewedBy uuid.UUID, note string) (*types.StockAdjustment, error)
+
+	// Reporting operations
+	Summarize(ctx context.Context, filter types.AdjustmentReportFilter, group types.AdjustmentReportGroup) ([]types.AdjustmentTotal, error)
+}
+
+type stockAdjustmentRepository struct {
+	db *sql.DB
+}
+
+func NewStockAdjustmentRepository(db *sql.DB) StockAdjustmentRepository {
+	return &stockAdjustmentRepository{db: db}
+}
+
+const reasonColumns = `id, organization_id, code, name, COALESCE(description, ''), requires_note, active, created_at`
+
+func scanReason(row interface{ Scan(...interface{}) error }) (*types.AdjustmentReason, error) {
+	var reason types.AdjustmentReason
+	var orgID uuid.NullUUID
+	if err := row.Scan(
+		&reason.ID, &orgID, &reason.Code, &reason.Name, &reason.Description,
+		&reason.RequiresNote, &reason.Active, &reason.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if orgID.Valid {
+		reason.OrganizationID = &orgID.UUID
+	}
+	return &reason, nil
+}
+
+// ListReasons retrieves the reasons available to an organization, its own
+// reasons taking the place of system reasons with the same code
+func (r *stockAdjustmentRepository) ListReasons(ctx context.Context, orgID uuid.UUID) ([]types.AdjustmentReason, error) {
+	query := `
+		SELECT DISTINCT ON (code) ` + reasonColumns + `
+		FROM inventory_adjustment_reasons
+		WHERE organization_id = $1 OR organization_id IS NULL
+		ORDER BY code, organization_id NULLS LAST
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adjustment reasons: %w", err)
+	}
+	defer rows.Close()
+
+	var reasons []types.AdjustmentReason
+	for rows.Next() {
+		reason, err := scanReason(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan adjustment reason: %w", err)
+		}
+		reasons = append(reasons, *reason)
+	}
+	return reasons, rows.Err()
+}
+
+// FindReason retrieves the reason of a code available to an organization
+func (r *stockAdjustmentRepository) FindReason(ctx context.Context, orgID uuid.UUID, code string) (*types.AdjustmentReason, error) {
+	query := `
+		SELECT ` + reasonColumns + `
+		FROM inventory_adjustment_reasons
+		WHERE code = $2 AND (organization_id = $1 OR organization_id IS NULL)
+		ORDER BY organization_id NULLS LAST
+		LIMIT 1
+	`
+
+	reason, err := scanReason(r.db.QueryRowContext(ctx, query, orgID, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find adjustment reason: %w", err)
+	}
+	return reason, nil
+}
+
+// SaveReason creates or updates the organization's reason of a code
+func (r *stockAdjustmentRepository) SaveReason(ctx context.Context, orgID uuid.UUID, request types.AdjustmentReasonRequest) (*types.AdjustmentReason, error) {
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+
+	query := `
+		INSERT INTO inventory_adjustment_reasons (organization_id, code, name, description, requires_note, active)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		ON CONFLICT (organization_id, code) WHERE organization_id IS NOT NULL DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			requires_note = EXCLUDED.requires_note,
+			active = EXCLUDED.active,
+			updated_at = NOW()
+		RETURNING ` + reasonColumns
+
+	reason, err := scanReason(r.db.QueryRowContext(ctx, query,
+		orgID, request.Code, request.Name, request.Description, request.RequiresNote, active,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save adjustment reason: %w", err)
+	}
+	return reason, nil
+}
+
+// GetApprovalSettings returns the organization's approval settings, or nil
+// when it has none
+func (r *stockAdjustmentRepository) GetApprovalSettings(ctx context.Context, orgID uuid.UUID) (*types.AdjustmentApprovalSettings, error) {
+	query := `SELECT settings->'inventory_adjustment_approval' FROM organizations WHERE id = $1 AND deleted_at IS NULL`
+
+	var settingsJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(&settingsJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get adjustment approval settings: %w", err)
+	}
+	if len(settingsJSON) == 0 {
+		return nil, nil
+	}
+
+	var settings types.AdjustmentApprovalSettings
+	if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+		return nil, fmt.Errorf("invalid adjustment approval settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveApprovalSettings replaces the "inventory_adjustment_approval" key of
+// the organization's settings, leaving other settings untouched
+func (r *stockAdjustmentRepository) SaveApprovalSettings(ctx context.Context, orgID uuid.UUID, settings types.AdjustmentApprovalSettings) error {
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal adjustment approval settings: %w", err)
+	}
+
+	query := `
+		UPDATE organizations
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{inventory_adjustment_approval}', $2::jsonb), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	if _, err := r.db.ExecContext(ctx, query, orgID, string(settingsJSON)); err != nil {
+		return fmt.Errorf("failed to save adjustment approval settings: %w", err)
+	}
+	return nil
+}
+
+// FindStock retrieves the untracked quantity on hand and the cost of a
+// product at an internal location, or nil when either does not exist
+func (r *stockAdjustmentRepository) FindStock(ctx context.Context, orgID, productID, locationID uuid.UUID) (*types.AdjustmentStock, error) {
+	query := `
+		SELECT p.name, COALESCE(l.complete_name, l.name), COALESCE(q.quantity, 0), COALESCE(p.standard_price, 0)
+		FROM products p
+		JOIN stock_locations l ON l.id = $3 AND l.organization_id = $1 AND l.usage = 'internal' AND l.deleted_at IS NULL
+		LEFT JOIN stock_quants q ON q.product_id = p.id AND q.location_id = l.id
+			AND q.lot_id IS NULL AND q.package_id IS NULL AND q.owner_id IS NULL
+		WHERE p.id = $2 AND p.organization_id = $1 AND p.deleted_at IS NULL
+	`
+
+	var stock types.AdjustmentStock
+	err := r.db.QueryRowContext(ctx, query, orgID, productID, locationID).Scan(
+		&stock.ProductName, &stock.LocationName, &stock.OnHand, &stock.UnitCost,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find adjustment stock: %w", err)
+	}
+	return &stock, nil
+}
+
+const adjustmentColumns = `
+	a.id, a.organization_id, a.product_id, COALESCE(p.name, ''), a.location_id,
+	COALESCE(l.complete_name, l.name, ''), a.reason_code, COALESCE(a.note, ''),
+	a.quantity_before, a.quantity, a.unit_cost, a.value, a.status, a.requested_by,
+	a.requested_at, a.reviewed_by, a.reviewed_at, COALESCE(a.review_note, ''),
+	a.applied_at, a.stock_move_id
+`
+
+const adjustmentFrom = `
+	FROM inventory_stock_adjustments a
+	LEFT JOIN products p ON p.id = a.product_id
+	LEFT JOIN stock_locations l ON l.id = a.location_id
+`
+
+func scanAdjustment(row interface{ Scan(...interface{}) error }) (*types.StockAdjustment, error) {
+	var a types.StockAdjustment
+	var reviewedBy, stockMoveID uuid.NullUUID
+	var reviewedAt, appliedAt sql.NullTime
+	if err := row.Scan(
+		&a.ID, &a.OrganizationID, &a.ProductID, &a.ProductName, &a.LocationID,
+		&a.LocationName, &a.ReasonCode, &a.Note,
+		&a.QuantityBefore, &a.Quantity, &a.UnitCost, &a.Value, &a.Status, &a.RequestedBy,
+		&a.RequestedAt, &reviewedBy, &reviewedAt, &a.ReviewNote,
+		&appliedAt, &stockMoveID,
+	); err != nil {
+		return nil, err
+	}
+	if reviewedBy.Valid {
+		a.ReviewedBy = &reviewedBy.UUID
+	}
+	if reviewedAt.Valid {
+		a.ReviewedAt = &reviewedAt.Time
+	}
+	if appliedAt.Valid {
+		a.AppliedAt = &appliedAt.Time
+	}
+	if stockMoveID.Valid {
+		a.StockMoveID = &stockMoveID.UUID
+	}
+	return &a, nil
+}
+
+// Create records a pending adjustment, applying it in the same transaction
+// when its status is applied
+func (r *stockAdjustmentRepository) Create(ctx context.Context, adjustment types.StockAdjustment) (*types.StockAdjustment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO inventory_stock_adjustments (
+			organization_id, product_id, location_id, reason_code, note,
+			quantity_before, quantity, unit_cost, value, status, requested_by
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, 'pending', $10)
+		RETURNING id
+	`
+	var id uuid.UUID
+	if err := tx.QueryRowContext(ctx, query,
+		adjustment.OrganizationID, adjustment.ProductID, adjustment.LocationID,
+		adjustment.ReasonCode, adjustment.Note, adjustment.QuantityBefore,
+		adjustment.Quantity, adjustment.UnitCost, adjustment.Value, adjustment.RequestedBy,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create stock adjustment: %w", err)
+	}
+
+	if adjustment.Status == types.StockAdjustmentStatusApplied {
+		if err := applyAdjustment(ctx, tx, adjustment.OrganizationID, id, nil, ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock adjustment: %w", err)
+	}
+
+	return r.FindByID(ctx, adjustment.OrganizationID, id)
+}
+
+// FindByID retrieves an adjustment, or nil when it does not exist
+func (r *stockAdjustmentRepository) FindByID(ctx context.Context, orgID, id uuid.UUID) (*types.StockAdjustment, error) {
+	query := `SELECT ` + adjustmentColumns + adjustmentFrom + ` WHERE a.organization_id = $1 AND a.id = $2`
+
+	adjustment, err := scanAdjustment(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock adjustment: %w", err)
+	}
+	return adjustment, nil
+}
+
+// List retrieves adjustments, most recently requested first
+func (r *stockAdjustmentRepository) List(ctx context.Context, filter types.StockAdjustmentFilter) ([]types.StockAdjustment, error) {
+	query := `SELECT ` + adjustmentColumns + adjustmentFrom + ` WHERE a.organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND a.status = $%d", len(args))
+	}
+	if filter.ReasonCode != "" {
+		args = append(args, filter.ReasonCode)
+		query += fmt.Sprintf(" AND a.reason_code = $%d", len(args))
+	}
+	if filter.ProductID != nil {
+		args = append(args, *filter.ProductID)
+		query += fmt.Sprintf(" AND a.product_id = $%d", len(args))
+	}
+	if filter.LocationID != nil {
+		args = append(args, *filter.LocationID)
+		query += fmt.Sprintf(" AND a.location_id = $%d", len(args))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY a.requested_at DESC, a.id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []types.StockAdjustment
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock adjustment: %w", err)
+		}
+		adjustments = append(adjustments, *adjustment)
+	}
+	return adjustments, rows.Err()
+}
+
+// Apply books a pending adjustment. It returns nil when the adjustment does
+// not exist and ErrAdjustmentNotPending when it was already reviewed.
+func (r *stockAdjustmentRepository) Apply(ctx context.Context, orgID, id uuid.UUID, reviewedBy *uuid.UUID, note string) (*types.StockAdjustment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := applyAdjustment(ctx, tx, orgID, id, reviewedBy, note); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stock adjustment: %w", err)
+	}
+
+	return r.FindByID(ctx, orgID, id)
+}
+
+// applyAdjustment moves the adjusted quantity between the adjusted location
+// and the organization's inventory loss location, and marks the adjustment
+// applied
+func applyAdjustment(ctx context.Context, tx *sql.Tx, orgID, id uuid.UUID, reviewedBy *uuid.UUID, note string) error {
+	var productID, locationID uuid.UUID
+	var reasonCode, status string
+	var quantity float64
+	err := tx.QueryRowContext(ctx, `
+		SELECT product_id, location_id, reason_code, quantity, status
+		FROM inventory_stock_adjustments
+		WHERE organization_id = $1 AND id = $2
+		FOR UPDATE
+	`, orgID, id).Scan(&productID, &locationID, &reasonCode, &quantity, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return err
+		}
+		return fmt.Errorf("failed to lock stock adjustment: %w", err)
+	}
+	if status != string(types.StockAdjustmentStatusPending) {
+		return ErrAdjustmentNotPending
+	}
+
+	lossLocationID, err := virtualLocation(ctx, tx, orgID, "inventory", "Virtual Locations/Inventory adjustment")
+	if err != nil {
+		return err
+	}
+
+	// Gains come from the loss location and losses go to it, as in a physical inventory
+	from, to, moved := lossLocationID, locationID, quantity
+	if quantity < 0 {
+		from, to, moved = locationID, lossLocationID, -quantity
+	}
+
+	var moveID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_moves (
+			organization_id, name, product_id, product_uom_qty, location_id, location_dest_id,
+			state, origin, reference, note, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, 'done', 'stock_adjustment', $7, NULLIF($8, ''), $9)
+		RETURNING id
+	`, orgID, "Stock adjustment: "+reasonCode, productID, moved, from, to,
+		id.String(), note, reviewedBy,
+	).Scan(&moveID)
+	if err != nil {
+		return fmt.Errorf("failed to create adjustment stock move: %w", err)
+	}
+
+	if err := adjustQuant(ctx, tx, orgID, productID, locationID, nil, nil, quantity); err != nil {
+		return err
+	}
+	if err := adjustQuant(ctx, tx, orgID, productID, lossLocationID, nil, nil, -quantity); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE inventory_stock_adjustments
+		SET status = 'applied', stock_move_id = $3, applied_at = NOW(),
+			reviewed_by = $4, reviewed_at = CASE WHEN $4::uuid IS NULL THEN NULL ELSE NOW() END,
+			review_note = NULLIF($5, '')
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, id, moveID, reviewedBy, note)
+	if err != nil {
+		return fmt.Errorf("failed to apply stock adjustment: %w", err)
+	}
+	return nil
+}
+
+// Reject rejects a pending adjustment. It returns nil when the adjustment
+// does not exist and ErrAdjustmentNotPending when it was already reviewed.
+func (r *stockAdjustmentRepository) Reject(ctx context.Context, orgID, id, reviewedBy uuid.UUID, note string) (*types.StockAdjustment, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE inventory_stock_adjustments
+		SET status = 'rejected', reviewed_by = $3, reviewed_at = NOW(), review_note = NULLIF($4, '')
+		WHERE organization_id = $1 AND id = $2 AND status = 'pending'
+	`, orgID, id, reviewedBy, note)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject stock adjustment: %w", err)
+	}
+	rejected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment, err := r.FindByID(ctx, orgID, id)
+	if err != nil || adjustment == nil {
+		return nil, err
+	}
+	if rejected == 0 {
+		return nil, ErrAdjustmentNotPending
+	}
+	return adjustment, nil
+}
+
+// Summarize totals the adjustments applied in the report's date range by
+// reason, requesting user or period. An empty group returns one overall total.
+func (r *stockAdjustmentRepository) Summarize(ctx context.Context, filter types.AdjustmentReportFilter, group types.AdjustmentReportGroup) ([]types.AdjustmentTotal, error) {
+	args := []interface{}{filter.OrganizationID, filter.From, filter.To}
+
+	var key, label, order string
+	switch group {
+	case types.AdjustmentReportByReason:
+		key = `a.reason_code`
+		label = `COALESCE(reason.name, a.reason_code)`
+		order = `losses DESC, 1`
+	case types.AdjustmentReportByUser:
+		key = `a.requested_by::text`
+		label = `COALESCE(u.email, a.requested_by::text)`
+		order = `losses DESC, 1`
+	case types.AdjustmentReportByPeriod:
+		args = append(args, filter.Period)
+		key = fmt.Sprintf(`to_char(date_trunc($%d, a.applied_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD')`, len(args))
+		label = key
+		order = `1`
+	case "":
+		key, label = `''`, `''`
+	default:
+		return nil, fmt.Errorf("unknown adjustment report group %q", group)
+	}
+
+	query := `
+		SELECT ` + key + `, ` + label + `, COUNT(*),
+			COALESCE(SUM(GREATEST(a.quantity, 0)), 0), COALESCE(SUM(GREATEST(-a.quantity, 0)), 0),
+			COALESCE(SUM(GREATEST(a.value, 0)), 0), COALESCE(SUM(GREATEST(-a.value, 0)), 0) AS losses,
+			COALESCE(SUM(a.value), 0)
+		FROM inventory_stock_adjustments a
+		LEFT JOIN LATERAL (
+			SELECT name FROM inventory_adjustment_reasons
+			WHERE code = a.reason_code AND (organization_id = a.organization_id OR organization_id IS NULL)
+			ORDER BY organization_id NULLS LAST
+			LIMIT 1
+		) reason ON true
+		LEFT JOIN auth.users u ON u.id = a.requested_by
+		WHERE a.organization_id = $1 AND a.status = 'applied'
+			AND a.applied_at >= $2 AND a.applied_at < $3
+	`
+	if filter.LocationID != nil {
+		args = append(args, *filter.LocationID)
+		query += fmt.Sprintf(" AND a.location_id = $%d", len(args))
+	}
+	if group != "" {
+		query += ` GROUP BY 1, 2 ORDER BY ` + order
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize stock adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []types.AdjustmentTotal{}
+	for rows.Next() {
+		var t types.AdjustmentTotal
+		if err := rows.Scan(
+			&t.Key, &t.Label, &t.Adjustments, &t.QuantityIn, &t.QuantityOut,
+			&t.Gains, &t.Losses, &t.NetValue,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan adjustment total: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+
+	"github.com/google/uuid"
+)
+
+// virtualLocation returns the organization's first active location of a
+// virtual usage such as inventory or supplier, creating it on first use
+// under its complete name
+func virtualLocation(ctx context.Context, tx *sql.Tx, orgID uuid.UUID, usage, completeName string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM stock_locations
+		WHERE organization_id = $1 AND usage = $2 AND active = true AND deleted_at IS NULL
+		ORDER BY created_at
+		LIMIT 1
+	`, orgID, usage).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to find %s location: %w", usage, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_locations (organization_id, name, complete_name, usage)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, orgID, path.Base(completeName), completeName, usage).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create %s location: %w", usage, err)
+	}
+	return id, nil
+}
+
+// adjustQuant adds a signed quantity to the package-less quant of a product
+// at a location with the given lot and owner. The quant unique constraint
+// does not cover NULL lots, packages and owners, so the quant is updated
+// before it is inserted.
+func adjustQuant(ctx context.Context, tx *sql.Tx, orgID, productID, locationID uuid.UUID, lotID, ownerID *uuid.UUID, quantity float64) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE stock_quants
+		SET quantity = quantity + $6, updated_at = NOW()
+		WHERE organization_id = $1 AND product_id = $2 AND location_id = $3
+			AND lot_id IS NOT DISTINCT FROM $4 AND package_id IS NULL AND owner_id IS NOT DISTINCT FROM $5
+	`, orgID, productID, locationID, lotID, ownerID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to update stock quant: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO stock_quants (organization_id, product_id, location_id, lot_id, owner_id, quantity, in_date)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, orgID, productID, locationID, lotID, ownerID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to create stock quant: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
+
+	"github.com/google/uuid"
+)
+
+type forceRefreshKey struct{}
+
+// WithForcedRefresh marks ctx so cached analytics are recomputed rather
+// than served from the cache
+func WithForcedRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+func forcedRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return refresh
+}
+
+// CachedAnalyticsService serves the dashboard and summary analytics from a
+// stale-while-revalidate cache, so they return at once and are recomputed
+// in the background once stale. Every other analytics call goes straight
+// to the wrapped service.
+type CachedAnalyticsService struct {
+	*AnalyticsService
+	dashboards *cache.SWR[map[string]interface{}]
+	summaries  *cache.SWR[*types.AnalyticsSummary]
+}
+
+func NewCachedAnalyticsService(analyticsService *AnalyticsService, opts cache.Options, logger *slog.Logger) *CachedAnalyticsService {
+	return &CachedAnalyticsService{
+		AnalyticsService: analyticsService,
+		dashboards:       cache.NewSWR[map[string]interface{}](opts, logger),
+		summaries:        cache.NewSWR[*types.AnalyticsSummary](opts, logger),
+	}
+}
+
+// GetInventoryDashboard returns the cached dashboard, with the time it was
+// computed under generated_at
+func (s *CachedAnalyticsService) GetInventoryDashboard(ctx context.Context, orgID uuid.UUID) (map[string]interface{}, error) {
+	entry, err := s.dashboards.Get(ctx, analyticsCacheKey(orgID, "dashboard"), forcedRefresh(ctx),
+		func(ctx context.Context) (map[string]interface{}, error) {
+			return s.AnalyticsService.GetInventoryDashboard(ctx, orgID)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the cached map so that callers never share it
+	dashboard := make(map[string]interface{}, len(entry.Value)+1)
+	for k, v := range entry.Value {
+		dashboard[k] = v
+	}
+	dashboard["generated_at"] = entry.GeneratedAt
+	return dashboard, nil
+}
+
+// GetValuationSummary returns the cached valuation summary
+func (s *CachedAnalyticsService) GetValuationSummary(ctx context.Context, orgID uuid.UUID) (*types.AnalyticsSummary, error) {
+	return s.summary(ctx, orgID, "valuation_summary", s.AnalyticsService.GetValuationSummary)
+}
+
+// GetDeadStockSummary returns the cached dead stock summary
+func (s *CachedAnalyticsService) GetDeadStockSummary(ctx context.Context, orgID uuid.UUID) (*types.AnalyticsSummary, error) {
+	return s.summary(ctx, orgID, "dead_stock_summary", s.AnalyticsService.GetDeadStockSummary)
+}
+
+// RefreshOrganizationAnalytics refreshes the organization's analytics and
+// drops its cached results, which no longer reflect them
+func (s *CachedAnalyticsService) RefreshOrganizationAnalytics(ctx context.Context, orgID uuid.UUID) error {
+	if err := s.AnalyticsService.RefreshOrganizationAnalytics(ctx, orgID); err != nil {
+		return err
+	}
+	prefix := analyticsCacheKey(orgID, "")
+	s.dashboards.Invalidate(prefix)
+	s.summaries.Invalidate(prefix)
+	return nil
+}
+
+func (s *CachedAnalyticsService) summary(ctx context.Context, orgID uuid.UUID, name string,
+	load func(ctx context.Context, orgID uuid.UUID) (*types.AnalyticsSummary, error)) (*types.AnalyticsSummary, error) {
+	entry, err := s.summaries.Get(ctx, analyticsCacheKey(orgID, name), forcedRefresh(ctx),
+		func(ctx context.Context) (*types.AnalyticsSummary, error) {
+			return load(ctx, orgID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if entry.Value == nil {
+		return nil, nil
+	}
+
+	summary := *entry.Value
+	generatedAt := entry.GeneratedAt
+	summary.GeneratedAt = &generatedAt
+	return &summary, nil
+}
+
+func analyticsCacheKey(orgID uuid.UUID, name string) string {
+	return orgID.String() + ":" + name
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAnalyticsRepository mocks the analytics queries used by summaries
+type MockAnalyticsRepository struct {
+	repository.AnalyticsRepository
+	mock.Mock
+}
+
+func (m *MockAnalyticsRepository) GetValuationSummary(ctx context.Context, orgID uuid.UUID) (*types.AnalyticsSummary, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(*types.AnalyticsSummary), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) RefreshOrganizationAnalytics(ctx context.Context, orgID uuid.UUID) error {
+	args := m.Called(ctx, orgID)
+	return args.Error(0)
+}
+
+func TestCachedAnalyticsSummary(t *testing.T) {
+	repo := &MockAnalyticsRepository{}
+	svc := NewCachedAnalyticsService(NewAnalyticsService(repo), cache.Options{}, nil)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	repo.On("GetValuationSummary", mock.Anything, orgID).Return(&types.AnalyticsSummary{OrganizationID: orgID, TotalProducts: 3}, nil).Twice()
+	repo.On("RefreshOrganizationAnalytics", mock.Anything, orgID).Return(nil).Once()
+
+	first, err := svc.GetValuationSummary(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, first.TotalProducts)
+	require.NotNil(t, first.GeneratedAt)
+
+	// Served from the cache
+	second, err := svc.GetValuationSummary(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	repo.AssertNumberOfCalls(t, "GetValuationSummary", 1)
+
+	// A forced refresh recomputes it
+	_, err = svc.GetValuationSummary(WithForcedRefresh(ctx), orgID)
+	require.NoError(t, err)
+	repo.AssertNumberOfCalls(t, "GetValuationSummary", 2)
+
+	// Refreshing the analytics drops the cached summary
+	require.NoError(t, svc.RefreshOrganizationAnalytics(ctx, orgID))
+	repo.On("GetValuationSummary", mock.Anything, orgID).Return(&types.AnalyticsSummary{OrganizationID: orgID, TotalProducts: 4}, nil).Once()
+	third, err := svc.GetValuationSummary(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, third.TotalProducts)
+	repo.AssertExpectations(t)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
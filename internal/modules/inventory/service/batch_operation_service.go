@@ -578,7 +578,7 @@ func (s *batchOperationService) ValidateBatchOperationItem(ctx context.Context,
 
 	// Validate locations if provided
 	if item.SourceLocationID != nil && *item.SourceLocationID != uuid.Nil {
-		location, err := s.inventoryService.GetStockLocation(ctx, *item.SourceLocationID)
+		location, err := s.inventoryService.GetLocation(ctx, *item.SourceLocationID)
 		if err != nil {
 			return fmt.Errorf("failed to validate source location: %w", err)
 		}
@@ -588,7 +588,7 @@ func (s *batchOperationService) ValidateBatchOperationItem(ctx context.Context,
 	}
 
 	if item.DestLocationID != nil && *item.DestLocationID != uuid.Nil {
-		location, err := s.inventoryService.GetStockLocation(ctx, *item.DestLocationID)
+		location, err := s.inventoryService.GetLocation(ctx, *item.DestLocationID)
 		if err != nil {
 			return fmt.Errorf("failed to validate destination location: %w", err)
 		}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+
+	"github.com/google/uuid"
+)
+
+// MaxConsignmentStatementDays bounds the date range of a consignment statement
+const MaxConsignmentStatementDays = 366
+
+var (
+	// ErrInvalidConsignment is returned for consignment requests that fail validation
+	ErrInvalidConsignment = errors.New("invalid consignment request")
+
+	// ErrInsufficientConsignedStock is returned when consuming more consigned
+	// stock than the vendor has at the location
+	ErrInsufficientConsignedStock = repository.ErrInsufficientConsignedStock
+
+	// ErrNoPurchaseJournal is returned when billing without a purchase journal
+	ErrNoPurchaseJournal = repository.ErrNoPurchaseJournal
+)
+
+type ConsignmentService struct {
+	repo repository.ConsignmentRepository
+}
+
+func NewConsignmentService(repo repository.ConsignmentRepository) *ConsignmentService {
+	return &ConsignmentService{
+		repo: repo,
+	}
+}
+
+// SaveAgreement creates or replaces the agreement with a vendor. It returns
+// nil when the vendor does not exist.
+func (s *ConsignmentService) SaveAgreement(ctx context.Context, orgID uuid.UUID, request types.ConsignmentAgreementRequest) (*types.ConsignmentAgreement, error) {
+	if request.VendorID == uuid.Nil {
+		return nil, fmt.Errorf("%w: vendor_id is required", ErrInvalidConsignment)
+	}
+	if request.BillingPolicy == "" {
+		request.BillingPolicy = types.ConsignmentBillOnConsumption
+	}
+	if !request.BillingPolicy.IsValid() {
+		return nil, fmt.Errorf("%w: billing_policy must be consumption or manual", ErrInvalidConsignment)
+	}
+	request.Reference = strings.TrimSpace(request.Reference)
+
+	seen := make(map[uuid.UUID]bool, len(request.Items))
+	for _, item := range request.Items {
+		if item.ProductID == uuid.Nil {
+			return nil, fmt.Errorf("%w: every item needs a product_id", ErrInvalidConsignment)
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("%w: product %s is listed twice", ErrInvalidConsignment, item.ProductID)
+		}
+		if item.UnitPrice < 0 {
+			return nil, fmt.Errorf("%w: unit_price cannot be negative", ErrInvalidConsignment)
+		}
+		seen[item.ProductID] = true
+	}
+
+	return s.repo.SaveAgreement(ctx, orgID, request)
+}
+
+// GetAgreement returns an agreement, or nil when it does not exist
+func (s *ConsignmentService) GetAgreement(ctx context.Context, orgID, id uuid.UUID) (*types.ConsignmentAgreement, error) {
+	return s.repo.FindAgreement(ctx, orgID, id)
+}
+
+// ListAgreements lists the organization's consignment agreements
+func (s *ConsignmentService) ListAgreements(ctx context.Context, orgID uuid.UUID) ([]types.ConsignmentAgreement, error) {
+	agreements, err := s.repo.ListAgreements(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if agreements == nil {
+		agreements = []types.ConsignmentAgreement{}
+	}
+	return agreements, nil
+}
+
+// agreementItem returns the vendor's agreement and the price it sets for a product
+func (s *ConsignmentService) agreementItem(ctx context.Context, orgID, vendorID, productID uuid.UUID) (*types.ConsignmentAgreement, *types.ConsignmentItem, error) {
+	agreement, err := s.repo.FindAgreementByVendor(ctx, orgID, vendorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if agreement == nil {
+		return nil, nil, fmt.Errorf("%w: no consignment agreement with vendor %s", ErrInvalidConsignment, vendorID)
+	}
+	for i := range agreement.Items {
+		if agreement.Items[i].ProductID == productID {
+			return agreement, &agreement.Items[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: product %s is not consigned by vendor %s", ErrInvalidConsignment, productID, vendorID)
+}
+
+// Receive books stock the vendor keeps ownership of into an internal
+// location. The vendor's agreement must be active and list the product. It
+// returns nil when the product, location or lot does not exist.
+func (s *ConsignmentService) Receive(ctx context.Context, orgID, userID uuid.UUID, request types.ConsignmentReceiptRequest) (*types.ConsignmentReceipt, error) {
+	if request.VendorID == uuid.Nil || request.ProductID == uuid.Nil || request.LocationID == uuid.Nil {
+		return nil, fmt.Errorf("%w: vendor_id, product_id and location_id are required", ErrInvalidConsignment)
+	}
+	if request.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidConsignment)
+	}
+
+	agreement, _, err := s.agreementItem(ctx, orgID, request.VendorID, request.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if !agreement.Active {
+		return nil, fmt.Errorf("%w: the consignment agreement with vendor %s is inactive", ErrInvalidConsignment, request.VendorID)
+	}
+
+	request.Reference = strings.TrimSpace(request.Reference)
+	return s.repo.Receive(ctx, orgID, userID, request)
+}
+
+// Consume transfers consigned stock to the organization at the agreement's
+// price. Under the consumption billing policy the consumption is billed at
+// once; a consumption that could not be billed is still recorded, with the
+// reason in its BillingError.
+func (s *ConsignmentService) Consume(ctx context.Context, orgID, userID uuid.UUID, request types.ConsignmentConsumptionRequest) (*types.ConsignmentConsumption, error) {
+	if request.VendorID == uuid.Nil || request.ProductID == uuid.Nil || request.LocationID == uuid.Nil {
+		return nil, fmt.Errorf("%w: vendor_id, product_id and location_id are required", ErrInvalidConsignment)
+	}
+	if request.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidConsignment)
+	}
+
+	agreement, item, err := s.agreementItem(ctx, orgID, request.VendorID, request.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Reference = strings.TrimSpace(request.Reference)
+	consumption, err := s.repo.Consume(ctx, orgID, userID, *agreement, item.UnitPrice, request)
+	if err != nil || consumption == nil {
+		return nil, err
+	}
+
+	if agreement.BillingPolicy == types.ConsignmentBillOnConsumption {
+		bill, err := s.repo.Bill(ctx, orgID, userID, *agreement, []uuid.UUID{consumption.ID})
+		switch {
+		case err != nil:
+			consumption.BillingError = err.Error()
+		case bill != nil:
+			now := time.Now()
+			consumption.InvoiceID = &bill.InvoiceID
+			consumption.BilledAt = &now
+		}
+	}
+	return consumption, nil
+}
+
+// BillAgreement drafts one vendor bill for all unbilled consumptions of an
+// agreement. It returns nil when the agreement does not exist.
+func (s *ConsignmentService) BillAgreement(ctx context.Context, orgID, userID, agreementID uuid.UUID) (*types.ConsignmentBill, error) {
+	agreement, err := s.repo.FindAgreement(ctx, orgID, agreementID)
+	if err != nil || agreement == nil {
+		return nil, err
+	}
+
+	bill, err := s.repo.Bill(ctx, orgID, userID, *agreement, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bill == nil {
+		return nil, fmt.Errorf("%w: no unbilled consumptions", ErrInvalidConsignment)
+	}
+	return bill, nil
+}
+
+// ListConsumptions lists consumptions, most recent first
+func (s *ConsignmentService) ListConsumptions(ctx context.Context, filter types.ConsignmentConsumptionFilter) ([]types.ConsignmentConsumption, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	consumptions, err := s.repo.ListConsumptions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if consumptions == nil {
+		consumptions = []types.ConsignmentConsumption{}
+	}
+	return consumptions, nil
+}
+
+// ListOwnership breaks on-hand stock down into owned and consigned quantities
+// per product, location and lot
+func (s *ConsignmentService) ListOwnership(ctx context.Context, filter types.StockOwnershipFilter) ([]types.StockOwnership, error) {
+	stock, err := s.repo.ListOwnership(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if stock == nil {
+		stock = []types.StockOwnership{}
+	}
+	return stock, nil
+}
+
+// GetStatement reports to the vendor of an agreement what was received and
+// consumed in a date range. It returns nil when the agreement does not exist.
+func (s *ConsignmentService) GetStatement(ctx context.Context, orgID, agreementID uuid.UUID, from, to time.Time) (*types.ConsignmentStatement, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidConsignment)
+	}
+	if to.Sub(from) > MaxConsignmentStatementDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: statement range cannot exceed %d days", ErrInvalidConsignment, MaxConsignmentStatementDays)
+	}
+
+	agreement, err := s.repo.FindAgreement(ctx, orgID, agreementID)
+	if err != nil || agreement == nil {
+		return nil, err
+	}
+
+	lines, err := s.repo.StatementLines(ctx, orgID, *agreement, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return buildConsignmentStatement(*agreement, from, to, lines), nil
+}
+
+func buildConsignmentStatement(agreement types.ConsignmentAgreement, from, to time.Time, lines []types.ConsignmentStatementLine) *types.ConsignmentStatement {
+	statement := &types.ConsignmentStatement{
+		AgreementID: agreement.ID,
+		VendorID:    agreement.VendorID,
+		VendorName:  agreement.VendorName,
+		From:        from,
+		To:          to,
+		Lines:       lines,
+	}
+	if statement.Lines == nil {
+		statement.Lines = []types.ConsignmentStatementLine{}
+	}
+	for _, line := range lines {
+		statement.ConsumedAmount += line.ConsumedAmount
+		statement.BilledAmount += line.BilledAmount
+	}
+	statement.ConsumedAmount = math.Round(statement.ConsumedAmount*100) / 100
+	statement.BilledAmount = math.Round(statement.BilledAmount*100) / 100
+	statement.UnbilledAmount = math.Round((statement.ConsumedAmount-statement.BilledAmount)*100) / 100
+	return statement
+}
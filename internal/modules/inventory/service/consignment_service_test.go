@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockConsignmentRepository is a mock implementation of ConsignmentRepository
+type MockConsignmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockConsignmentRepository) SaveAgreement(ctx context.Context, orgID uuid.UUID, request types.ConsignmentAgreementRequest) (*types.ConsignmentAgreement, error) {
+	args := m.Called(ctx, orgID, request)
+	return args.Get(0).(*types.ConsignmentAgreement), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) FindAgreement(ctx context.Context, orgID, id uuid.UUID) (*types.ConsignmentAgreement, error) {
+	args := m.Called(ctx, orgID, id)
+	return args.Get(0).(*types.ConsignmentAgreement), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) FindAgreementByVendor(ctx context.Context, orgID, vendorID uuid.UUID) (*types.ConsignmentAgreement, error) {
+	args := m.Called(ctx, orgID, vendorID)
+	return args.Get(0).(*types.ConsignmentAgreement), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) ListAgreements(ctx context.Context, orgID uuid.UUID) ([]types.ConsignmentAgreement, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]types.ConsignmentAgreement), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) Receive(ctx context.Context, orgID, userID uuid.UUID, request types.ConsignmentReceiptRequest) (*types.ConsignmentReceipt, error) {
+	args := m.Called(ctx, orgID, userID, request)
+	return args.Get(0).(*types.ConsignmentReceipt), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) Consume(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, unitPrice float64, request types.ConsignmentConsumptionRequest) (*types.ConsignmentConsumption, error) {
+	args := m.Called(ctx, orgID, userID, agreement, unitPrice, request)
+	return args.Get(0).(*types.ConsignmentConsumption), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) ListOwnership(ctx context.Context, filter types.StockOwnershipFilter) ([]types.StockOwnership, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]types.StockOwnership), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) ListConsumptions(ctx context.Context, filter types.ConsignmentConsumptionFilter) ([]types.ConsignmentConsumption, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]types.ConsignmentConsumption), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) Bill(ctx context.Context, orgID, userID uuid.UUID, agreement types.ConsignmentAgreement, consumptionIDs []uuid.UUID) (*types.ConsignmentBill, error) {
+	args := m.Called(ctx, orgID, userID, agreement, consumptionIDs)
+	return args.Get(0).(*types.ConsignmentBill), args.Error(1)
+}
+
+func (m *MockConsignmentRepository) StatementLines(ctx context.Context, orgID uuid.UUID, agreement types.ConsignmentAgreement, from, to time.Time) ([]types.ConsignmentStatementLine, error) {
+	args := m.Called(ctx, orgID, agreement, from, to)
+	return args.Get(0).([]types.ConsignmentStatementLine), args.Error(1)
+}
+
+func TestConsignmentService_Consume(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	vendorID := uuid.New()
+	productID := uuid.New()
+	request := types.ConsignmentConsumptionRequest{
+		VendorID:   vendorID,
+		ProductID:  productID,
+		LocationID: uuid.New(),
+		Quantity:   4,
+	}
+
+	t.Run("bills on consumption", func(t *testing.T) {
+		agreement := &types.ConsignmentAgreement{
+			ID:            uuid.New(),
+			VendorID:      vendorID,
+			BillingPolicy: types.ConsignmentBillOnConsumption,
+			Items:         []types.ConsignmentItem{{ProductID: productID, UnitPrice: 7.5}},
+		}
+		consumption := &types.ConsignmentConsumption{ID: uuid.New(), Quantity: 4, UnitPrice: 7.5, Amount: 30}
+		invoiceID := uuid.New()
+
+		repo := new(MockConsignmentRepository)
+		repo.On("FindAgreementByVendor", ctx, orgID, vendorID).Return(agreement, nil)
+		repo.On("Consume", ctx, orgID, userID, *agreement, 7.5, request).Return(consumption, nil)
+		repo.On("Bill", ctx, orgID, userID, *agreement, []uuid.UUID{consumption.ID}).
+			Return(&types.ConsignmentBill{InvoiceID: invoiceID, AmountTotal: 30}, nil)
+
+		result, err := NewConsignmentService(repo).Consume(ctx, orgID, userID, request)
+		require.NoError(t, err)
+		require.NotNil(t, result.InvoiceID)
+		assert.Equal(t, invoiceID, *result.InvoiceID)
+		assert.Empty(t, result.BillingError)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("keeps the consumption when billing fails", func(t *testing.T) {
+		agreement := &types.ConsignmentAgreement{
+			ID:            uuid.New(),
+			VendorID:      vendorID,
+			BillingPolicy: types.ConsignmentBillOnConsumption,
+			Items:         []types.ConsignmentItem{{ProductID: productID, UnitPrice: 2}},
+		}
+		consumption := &types.ConsignmentConsumption{ID: uuid.New()}
+
+		repo := new(MockConsignmentRepository)
+		repo.On("FindAgreementByVendor", ctx, orgID, vendorID).Return(agreement, nil)
+		repo.On("Consume", ctx, orgID, userID, *agreement, 2.0, request).Return(consumption, nil)
+		repo.On("Bill", ctx, orgID, userID, *agreement, mock.Anything).Return((*types.ConsignmentBill)(nil), ErrNoPurchaseJournal)
+
+		result, err := NewConsignmentService(repo).Consume(ctx, orgID, userID, request)
+		require.NoError(t, err)
+		assert.Nil(t, result.InvoiceID)
+		assert.Equal(t, ErrNoPurchaseJournal.Error(), result.BillingError)
+	})
+
+	t.Run("manual billing leaves the consumption unbilled", func(t *testing.T) {
+		agreement := &types.ConsignmentAgreement{
+			ID:            uuid.New(),
+			VendorID:      vendorID,
+			BillingPolicy: types.ConsignmentBillManually,
+			Items:         []types.ConsignmentItem{{ProductID: productID, UnitPrice: 2}},
+		}
+
+		repo := new(MockConsignmentRepository)
+		repo.On("FindAgreementByVendor", ctx, orgID, vendorID).Return(agreement, nil)
+		repo.On("Consume", ctx, orgID, userID, *agreement, 2.0, request).Return(&types.ConsignmentConsumption{ID: uuid.New()}, nil)
+
+		result, err := NewConsignmentService(repo).Consume(ctx, orgID, userID, request)
+		require.NoError(t, err)
+		assert.Nil(t, result.InvoiceID)
+		repo.AssertNotCalled(t, "Bill", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects products outside the agreement", func(t *testing.T) {
+		repo := new(MockConsignmentRepository)
+		repo.On("FindAgreementByVendor", ctx, orgID, vendorID).Return(&types.ConsignmentAgreement{VendorID: vendorID}, nil)
+
+		_, err := NewConsignmentService(repo).Consume(ctx, orgID, userID, request)
+		assert.ErrorIs(t, err, ErrInvalidConsignment)
+	})
+}
+
+func TestConsignmentService_SaveAgreement_ValidationErrors(t *testing.T) {
+	service := NewConsignmentService(new(MockConsignmentRepository))
+	productID := uuid.New()
+
+	tests := []struct {
+		name    string
+		request types.ConsignmentAgreementRequest
+	}{
+		{"no vendor", types.ConsignmentAgreementRequest{}},
+		{"unknown billing policy", types.ConsignmentAgreementRequest{VendorID: uuid.New(), BillingPolicy: "weekly"}},
+		{"duplicate product", types.ConsignmentAgreementRequest{VendorID: uuid.New(), Items: []types.ConsignmentItem{{ProductID: productID}, {ProductID: productID}}}},
+		{"negative price", types.ConsignmentAgreementRequest{VendorID: uuid.New(), Items: []types.ConsignmentItem{{ProductID: productID, UnitPrice: -1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SaveAgreement(context.Background(), uuid.New(), tt.request)
+			assert.ErrorIs(t, err, ErrInvalidConsignment)
+		})
+	}
+}
+
+func TestBuildConsignmentStatement(t *testing.T) {
+	agreement := types.ConsignmentAgreement{ID: uuid.New(), VendorID: uuid.New(), VendorName: "Acme"}
+	lines := []types.ConsignmentStatementLine{
+		{ConsumedQuantity: 3, ConsumedAmount: 30.1, BilledAmount: 30.1},
+		{ConsumedQuantity: 2, ConsumedAmount: 10.2},
+	}
+
+	statement := buildConsignmentStatement(agreement, time.Now().AddDate(0, -1, 0), time.Now(), lines)
+	assert.Equal(t, "Acme", statement.VendorName)
+	assert.Equal(t, 40.3, statement.ConsumedAmount)
+	assert.Equal(t, 30.1, statement.BilledAmount)
+	assert.Equal(t, 10.2, statement.UnbilledAmount)
+}
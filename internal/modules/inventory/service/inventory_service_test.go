@@ -47,6 +47,11 @@ func (m *MockStockMoveRepository) GetByID(ctx context.Context, id uuid.UUID) (*t
 	return args.Get(0).(*types.StockMove), args.Error(1)
 }
 
+func (m *MockStockMoveRepository) GetByPickingID(ctx context.Context, pickingID uuid.UUID) ([]types.StockMove, error) {
+	args := m.Called(ctx, pickingID)
+	return args.Get(0).([]types.StockMove), args.Error(1)
+}
+
 func (m *MockStockMoveRepository) List(ctx context.Context, orgID uuid.UUID) ([]types.StockMove, error) {
 	args := m.Called(ctx, orgID)
 	return args.Get(0).([]types.StockMove), args.Error(1)
@@ -26,7 +26,7 @@ func (s *ProcurementGroupService) Create(ctx context.Context, orgID uuid.UUID, r
 }
 
 // GetByID retrieves a procurement group by ID
-func (s *ProcurementGroupService) GetByID(ctx context.Context, orgID uuid.UUID, id uuid.UUID) (*types.ProcurementGroup, error) {
+func (s *ProcurementGroupService) GetByID(ctx context.Context, id uuid.UUID) (*types.ProcurementGroup, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
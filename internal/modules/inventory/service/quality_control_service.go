@@ -7,6 +7,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
 	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	productsRepo "github.com/KevTiv/alieze-erp/internal/modules/products/repository"
 
 	"github.com/google/uuid"
 )
@@ -18,7 +19,22 @@ type QualityControlService struct {
 	checklistItemRepo   repository.QualityChecklistItemRepository
 	inspectionItemRepo  repository.QualityControlInspectionItemRepository
 	alertRepo           repository.QualityControlAlertRepository
-	inventoryRepo      repository.InventoryRepository
+	inventoryService    *InventoryService
+	productsRepo        productsRepo.ProductRepo
+	inspectors          InspectorQualifier
+	instruments         InstrumentChecker
+}
+
+// InspectorQualifier checks that a user holds the certifications required
+// to be assigned QC inspections
+type InspectorQualifier interface {
+	RequireInspector(ctx context.Context, orgID, userID uuid.UUID) error
+}
+
+// InstrumentChecker checks that measuring equipment is calibrated before
+// inspection items record it
+type InstrumentChecker interface {
+	RequireCalibrated(ctx context.Context, orgID, equipmentID uuid.UUID) error
 }
 
 // NewQualityControlService creates a new QualityControlService instance
@@ -28,7 +44,8 @@ func NewQualityControlService(
 	checklistItemRepo repository.QualityChecklistItemRepository,
 	inspectionItemRepo repository.QualityControlInspectionItemRepository,
 	alertRepo repository.QualityControlAlertRepository,
-	inventoryRepo repository.InventoryRepository,
+	inventoryService *InventoryService,
+	productsRepo productsRepo.ProductRepo,
 ) *QualityControlService {
 	return &QualityControlService{
 		inspectionRepo:      inspectionRepo,
@@ -36,10 +53,41 @@ func NewQualityControlService(
 		checklistItemRepo:   checklistItemRepo,
 		inspectionItemRepo:  inspectionItemRepo,
 		alertRepo:           alertRepo,
-		inventoryRepo:      inventoryRepo,
+		inventoryService:    inventoryService,
+		productsRepo:        productsRepo,
 	}
 }
 
+// SetInspectorQualifier makes inspections assignable only to qualified
+// inspectors. Without a qualifier any user can be assigned.
+func (s *QualityControlService) SetInspectorQualifier(qualifier InspectorQualifier) {
+	s.inspectors = qualifier
+}
+
+// requireInspector checks that the inspector may be assigned inspections of
+// the organization
+func (s *QualityControlService) requireInspector(ctx context.Context, orgID uuid.UUID, inspectorID *uuid.UUID) error {
+	if s.inspectors == nil || inspectorID == nil || *inspectorID == uuid.Nil {
+		return nil
+	}
+	return s.inspectors.RequireInspector(ctx, orgID, *inspectorID)
+}
+
+// SetInstrumentChecker makes inspection items record only calibrated
+// equipment. Without a checker any equipment can be recorded.
+func (s *QualityControlService) SetInstrumentChecker(checker InstrumentChecker) {
+	s.instruments = checker
+}
+
+// requireCalibrated checks that the equipment may be used to measure items
+// of the organization
+func (s *QualityControlService) requireCalibrated(ctx context.Context, orgID uuid.UUID, equipmentID *uuid.UUID) error {
+	if s.instruments == nil || equipmentID == nil || *equipmentID == uuid.Nil {
+		return nil
+	}
+	return s.instruments.RequireCalibrated(ctx, orgID, *equipmentID)
+}
+
 // Inspection Management
 
 func (s *QualityControlService) CreateInspection(ctx context.Context, inspection types.QualityControlInspection) (*types.QualityControlInspection, error) {
@@ -64,9 +112,12 @@ func (s *QualityControlService) CreateInspection(ctx context.Context, inspection
 	if inspection.Quantity <= 0 {
 		return nil, fmt.Errorf("quantity must be positive")
 	}
+	if err := s.requireInspector(ctx, inspection.OrganizationID, inspection.InspectorID); err != nil {
+		return nil, err
+	}
 
 	// Get product and location names for the inspection
-	product, err := s.inventoryRepo.GetProductByID(ctx, inspection.ProductID)
+	product, err := s.productsRepo.FindByID(ctx, inspection.ProductID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
@@ -75,7 +126,7 @@ func (s *QualityControlService) CreateInspection(ctx context.Context, inspection
 	}
 	inspection.ProductName = product.Name
 
-	location, err := s.inventoryRepo.GetLocation(ctx, inspection.LocationID)
+	location, err := s.inventoryService.GetLocation(ctx, inspection.LocationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get location: %w", err)
 	}
@@ -111,6 +162,18 @@ func (s *QualityControlService) ListInspectionsByStatus(ctx context.Context, org
 }
 
 func (s *QualityControlService) UpdateInspection(ctx context.Context, inspection types.QualityControlInspection) (*types.QualityControlInspection, error) {
+	if inspection.InspectorID != nil {
+		existing, err := s.GetInspection(ctx, inspection.ID)
+		if err != nil {
+			return nil, err
+		}
+		// Only a newly assigned inspector is checked
+		if existing.InspectorID == nil || *existing.InspectorID != *inspection.InspectorID {
+			if err := s.requireInspector(ctx, existing.OrganizationID, inspection.InspectorID); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return s.inspectionRepo.Update(ctx, inspection)
 }
 
@@ -152,6 +215,10 @@ func (s *QualityControlService) ListChecklists(ctx context.Context, organization
 	return s.checklistRepo.FindAll(ctx, organizationID)
 }
 
+func (s *QualityControlService) ListChecklistsByProduct(ctx context.Context, organizationID, productID uuid.UUID) ([]types.QualityControlChecklist, error) {
+	return s.checklistRepo.FindByProduct(ctx, organizationID, productID)
+}
+
 func (s *QualityControlService) ListActiveChecklists(ctx context.Context, organizationID uuid.UUID) ([]types.QualityControlChecklist, error) {
 	return s.checklistRepo.FindActive(ctx, organizationID)
 }
@@ -219,6 +286,15 @@ func (s *QualityControlService) CreateInspectionItem(ctx context.Context, item t
 	if item.Result == "" {
 		item.Result = "pending"
 	}
+	if item.EquipmentID != nil {
+		inspection, err := s.GetInspection(ctx, item.InspectionID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.requireCalibrated(ctx, inspection.OrganizationID, item.EquipmentID); err != nil {
+			return nil, err
+		}
+	}
 
 	return s.inspectionItemRepo.Create(ctx, item)
 }
@@ -239,6 +315,24 @@ func (s *QualityControlService) ListInspectionItems(ctx context.Context, inspect
 }
 
 func (s *QualityControlService) UpdateInspectionItem(ctx context.Context, item types.QualityControlInspectionItem) (*types.QualityControlInspectionItem, error) {
+	// Only a new measurement needs a calibrated instrument, so notes can
+	// still be edited after the equipment lapses
+	if item.EquipmentID != nil {
+		existing, err := s.GetInspectionItem(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing.EquipmentID == nil || *existing.EquipmentID != *item.EquipmentID || existing.Result != item.Result {
+			inspection, err := s.GetInspection(ctx, existing.InspectionID)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.requireCalibrated(ctx, inspection.OrganizationID, item.EquipmentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return s.inspectionItemRepo.Update(ctx, item)
 }
 
@@ -307,13 +401,16 @@ func (s *QualityControlService) DeleteAlert(ctx context.Context, id uuid.UUID) e
 
 func (s *QualityControlService) CreateInspectionFromStockMove(ctx context.Context, stockMoveID, inspectorID uuid.UUID, checklistID *uuid.UUID, inspectionMethod string, sampleSize *int) (*types.QualityControlInspection, error) {
 	// Validate stock move exists
-	stockMove, err := s.inventoryRepo.GetMove(ctx, stockMoveID)
+	stockMove, err := s.inventoryService.GetMove(ctx, stockMoveID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stock move: %w", err)
 	}
 	if stockMove == nil {
 		return nil, fmt.Errorf("stock move not found")
 	}
+	if err := s.requireInspector(ctx, stockMove.OrganizationID, &inspectorID); err != nil {
+		return nil, err
+	}
 
 	return s.inspectionRepo.CreateFromStockMove(ctx, stockMoveID, inspectorID, checklistID, inspectionMethod, sampleSize)
 }
@@ -359,6 +456,11 @@ func (s *QualityControlService) CompleteInspection(ctx context.Context, inspecti
 	if err != nil {
 		return fmt.Errorf("failed to get inspection: %w", err)
 	}
+	for _, result := range results {
+		if err := s.requireCalibrated(ctx, inspection.OrganizationID, result.EquipmentID); err != nil {
+			return err
+		}
+	}
 
 	// Complete the inspection
 	err = s.inspectionRepo.CompleteInspection(ctx, inspectionID, status, results)
@@ -412,7 +514,7 @@ func (s *QualityControlService) CreateAlertFromInspection(ctx context.Context, i
 
 func (s *QualityControlService) StartQualityControlWorkflow(ctx context.Context, stockMoveID, inspectorID uuid.UUID) (*types.QualityControlInspection, error) {
 	// 1. Get stock move details
-	stockMove, err := s.inventoryRepo.GetMove(ctx, stockMoveID)
+	stockMove, err := s.inventoryService.GetMove(ctx, stockMoveID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stock move: %w", err)
 	}
@@ -442,7 +544,7 @@ func (s *QualityControlService) StartQualityControlWorkflow(ctx context.Context,
 func (s *QualityControlService) ProcessQualityControlResult(ctx context.Context, inspectionID uuid.UUID, results []types.QualityControlInspectionItem) (*types.QualityControlInspection, error) {
 	// 1. Update all inspection items with results
 	for _, result := range results {
-		err := s.inspectionItemRepo.UpdateResult(ctx, result.ID, result.Result, result.Notes)
+		err := s.inspectionItemRepo.UpdateResult(ctx, result.ID, result.Result, stringValue(result.Notes))
 		if err != nil {
 			return nil, fmt.Errorf("failed to update inspection item result: %w", err)
 		}
@@ -499,7 +601,7 @@ func (s *QualityControlService) HandleQualityControlDisposition(ctx context.Cont
 	}
 
 	// Update the inspection
-	err = s.UpdateInspectionStatus(ctx, inspectionID, status, inspection.DefectType, inspection.DefectDescription,
+	err = s.UpdateInspectionStatus(ctx, inspectionID, status, stringValue(inspection.DefectType), stringValue(inspection.DefectDescription),
 		inspection.DefectQuantity, inspection.QualityRating, inspection.ComplianceNotes, &disposition)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update inspection status: %w", err)
@@ -565,3 +667,11 @@ func (s *QualityControlService) GetQualityControlDashboard(ctx context.Context,
 		"last_updated":         time.Now(),
 	}, nil
 }
+
+// stringValue returns the string a pointer refers to, or "" when it is nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -15,20 +15,20 @@ import (
 type ReplenishmentService struct {
 	replenishmentRuleRepo repository.ReplenishmentRuleRepository
 	replenishmentOrderRepo repository.ReplenishmentOrderRepository
-	inventoryRepo         repository.InventoryRepository
+	inventoryService      *InventoryService
 	productsRepo          productsRepo.ProductRepo
 }
 
 func NewReplenishmentService(
 	replenishmentRuleRepo repository.ReplenishmentRuleRepository,
 	replenishmentOrderRepo repository.ReplenishmentOrderRepository,
-	inventoryRepo repository.InventoryRepository,
+	inventoryService *InventoryService,
 	productsRepo productsRepo.ProductRepo,
 ) *ReplenishmentService {
 	return &ReplenishmentService{
 		replenishmentRuleRepo: replenishmentRuleRepo,
 		replenishmentOrderRepo: replenishmentOrderRepo,
-		inventoryRepo:         inventoryRepo,
+		inventoryService:      inventoryService,
 		productsRepo:          productsRepo,
 	}
 }
@@ -187,7 +187,7 @@ func (s *ReplenishmentService) CheckReplenishmentNeeds(ctx context.Context, orga
 		// In a real implementation, we'd check all products against their reorder points
 		if rule.ProductID != nil {
 			// Get current stock for this product
-			stock, err := s.inventoryRepo.GetProductStock(ctx, organizationID, *rule.ProductID)
+			stock, err := s.inventoryService.GetProductStock(ctx, organizationID, *rule.ProductID)
 			if err != nil {
 				continue // Skip if we can't get stock
 			}
@@ -228,7 +228,7 @@ func (s *ReplenishmentService) CheckReplenishmentNeeds(ctx context.Context, orga
 				// Get location name if available
 				locationName := ""
 				if rule.LocationID != nil {
-					location, err := s.inventoryRepo.GetLocation(ctx, *rule.LocationID)
+					location, err := s.inventoryService.GetLocation(ctx, *rule.LocationID)
 					if err == nil && location != nil {
 						locationName = location.Name
 					}
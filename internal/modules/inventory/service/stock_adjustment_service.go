@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultAdjustmentApprovalThreshold is the value from which adjustments
+	// of organizations without approval settings wait for approval
+	DefaultAdjustmentApprovalThreshold = 500.0
+
+	// MaxAdjustmentReportDays bounds the date range of an adjustment report
+	MaxAdjustmentReportDays = 366
+)
+
+var (
+	// ErrInvalidAdjustment is returned for adjustment requests, reasons,
+	// settings or reports that fail validation
+	ErrInvalidAdjustment = errors.New("invalid stock adjustment")
+
+	// ErrAdjustmentNotPending is returned when reviewing an adjustment that
+	// was already applied or rejected
+	ErrAdjustmentNotPending = repository.ErrAdjustmentNotPending
+)
+
+var reasonCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+type StockAdjustmentService struct {
+	repo repository.StockAdjustmentRepository
+}
+
+func NewStockAdjustmentService(repo repository.StockAdjustmentRepository) *StockAdjustmentService {
+	return &StockAdjustmentService{
+		repo: repo,
+	}
+}
+
+// ListReasons lists the reason codes available to an organization
+func (s *StockAdjustmentService) ListReasons(ctx context.Context, orgID uuid.UUID) ([]types.AdjustmentReason, error) {
+	reasons, err := s.repo.ListReasons(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if reasons == nil {
+		reasons = []types.AdjustmentReason{}
+	}
+	return reasons, nil
+}
+
+// SaveReason creates or updates one of the organization's reason codes. A
+// code of a system reason overrides it for the organization.
+func (s *StockAdjustmentService) SaveReason(ctx context.Context, orgID uuid.UUID, request types.AdjustmentReasonRequest) (*types.AdjustmentReason, error) {
+	request.Code = strings.ToLower(strings.TrimSpace(request.Code))
+	request.Name = strings.TrimSpace(request.Name)
+	if !reasonCodePattern.MatchString(request.Code) {
+		return nil, fmt.Errorf("%w: code must be up to 50 lowercase letters, digits or underscores", ErrInvalidAdjustment)
+	}
+	if request.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAdjustment)
+	}
+	return s.repo.SaveReason(ctx, orgID, request)
+}
+
+// GetApprovalSettings returns the organization's approval settings, or the
+// defaults when it has none
+func (s *StockAdjustmentService) GetApprovalSettings(ctx context.Context, orgID uuid.UUID) (*types.AdjustmentApprovalSettings, error) {
+	settings, err := s.repo.GetApprovalSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &types.AdjustmentApprovalSettings{Threshold: DefaultAdjustmentApprovalThreshold}
+	}
+	return settings, nil
+}
+
+// UpdateApprovalSettings replaces the organization's approval settings
+func (s *StockAdjustmentService) UpdateApprovalSettings(ctx context.Context, orgID uuid.UUID, settings types.AdjustmentApprovalSettings) (*types.AdjustmentApprovalSettings, error) {
+	if settings.Threshold < 0 {
+		return nil, fmt.Errorf("%w: threshold cannot be negative", ErrInvalidAdjustment)
+	}
+	for code, threshold := range settings.ReasonThresholds {
+		if threshold < 0 {
+			return nil, fmt.Errorf("%w: threshold of reason %s cannot be negative", ErrInvalidAdjustment, code)
+		}
+	}
+	if err := s.repo.SaveApprovalSettings(ctx, orgID, settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// RequestAdjustment records a manual stock adjustment. Adjustments below the
+// approval threshold of their reason are applied at once; the others wait
+// for another user to approve them. It returns nil when the product or the
+// internal location does not exist.
+func (s *StockAdjustmentService) RequestAdjustment(ctx context.Context, orgID, userID uuid.UUID, request types.StockAdjustmentRequest) (*types.StockAdjustment, error) {
+	if request.ProductID == uuid.Nil || request.LocationID == uuid.Nil {
+		return nil, fmt.Errorf("%w: product_id and location_id are required", ErrInvalidAdjustment)
+	}
+	if (request.Quantity == nil) == (request.NewQuantity == nil) {
+		return nil, fmt.Errorf("%w: exactly one of quantity and new_quantity is required", ErrInvalidAdjustment)
+	}
+	if request.NewQuantity != nil && *request.NewQuantity < 0 {
+		return nil, fmt.Errorf("%w: new_quantity cannot be negative", ErrInvalidAdjustment)
+	}
+
+	request.ReasonCode = strings.ToLower(strings.TrimSpace(request.ReasonCode))
+	request.Note = strings.TrimSpace(request.Note)
+	if request.ReasonCode == "" {
+		return nil, fmt.Errorf("%w: reason_code is required", ErrInvalidAdjustment)
+	}
+	reason, err := s.repo.FindReason(ctx, orgID, request.ReasonCode)
+	if err != nil {
+		return nil, err
+	}
+	if reason == nil || !reason.Active {
+		return nil, fmt.Errorf("%w: unknown reason code %s", ErrInvalidAdjustment, request.ReasonCode)
+	}
+	if reason.RequiresNote && request.Note == "" {
+		return nil, fmt.Errorf("%w: reason %s requires a note", ErrInvalidAdjustment, reason.Code)
+	}
+
+	stock, err := s.repo.FindStock(ctx, orgID, request.ProductID, request.LocationID)
+	if err != nil || stock == nil {
+		return nil, err
+	}
+
+	var quantity float64
+	if request.Quantity != nil {
+		quantity = *request.Quantity
+	} else {
+		quantity = *request.NewQuantity - stock.OnHand
+	}
+	if quantity == 0 {
+		return nil, fmt.Errorf("%w: adjustment does not change the quantity on hand", ErrInvalidAdjustment)
+	}
+	if stock.OnHand+quantity < 0 {
+		return nil, fmt.Errorf("%w: only %g on hand", ErrInvalidAdjustment, stock.OnHand)
+	}
+
+	settings, err := s.GetApprovalSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := types.StockAdjustment{
+		OrganizationID: orgID,
+		ProductID:      request.ProductID,
+		LocationID:     request.LocationID,
+		ReasonCode:     reason.Code,
+		Note:           request.Note,
+		QuantityBefore: stock.OnHand,
+		Quantity:       quantity,
+		UnitCost:       stock.UnitCost,
+		Value:          math.Round(quantity*stock.UnitCost*100) / 100,
+		Status:         types.StockAdjustmentStatusApplied,
+		RequestedBy:    userID,
+	}
+	if requiresApproval(*settings, adjustment) {
+		adjustment.Status = types.StockAdjustmentStatusPending
+	}
+	return s.repo.Create(ctx, adjustment)
+}
+
+// requiresApproval reports whether the absolute value of an adjustment
+// reaches the approval threshold of its reason
+func requiresApproval(settings types.AdjustmentApprovalSettings, adjustment types.StockAdjustment) bool {
+	threshold := settings.Threshold
+	if t, ok := settings.ReasonThresholds[adjustment.ReasonCode]; ok {
+		threshold = t
+	}
+	return math.Abs(adjustment.Value) >= threshold
+}
+
+// GetAdjustment returns an adjustment, or nil when it does not exist
+func (s *StockAdjustmentService) GetAdjustment(ctx context.Context, orgID, id uuid.UUID) (*types.StockAdjustment, error) {
+	return s.repo.FindByID(ctx, orgID, id)
+}
+
+// ListAdjustments lists adjustments, most recently requested first
+func (s *StockAdjustmentService) ListAdjustments(ctx context.Context, filter types.StockAdjustmentFilter) ([]types.StockAdjustment, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	adjustments, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if adjustments == nil {
+		adjustments = []types.StockAdjustment{}
+	}
+	return adjustments, nil
+}
+
+// ApproveAdjustment applies a pending adjustment. Users cannot approve their
+// own adjustments. It returns nil when the adjustment does not exist.
+func (s *StockAdjustmentService) ApproveAdjustment(ctx context.Context, orgID, id, userID uuid.UUID, review types.StockAdjustmentReview) (*types.StockAdjustment, error) {
+	adjustment, err := s.pendingAdjustment(ctx, orgID, id, userID)
+	if err != nil || adjustment == nil {
+		return nil, err
+	}
+	return s.repo.Apply(ctx, orgID, id, &userID, strings.TrimSpace(review.Note))
+}
+
+// RejectAdjustment rejects a pending adjustment, leaving stock untouched.
+// It returns nil when the adjustment does not exist.
+func (s *StockAdjustmentService) RejectAdjustment(ctx context.Context, orgID, id, userID uuid.UUID, review types.StockAdjustmentReview) (*types.StockAdjustment, error) {
+	review.Note = strings.TrimSpace(review.Note)
+	if review.Note == "" {
+		return nil, fmt.Errorf("%w: a note is required to reject an adjustment", ErrInvalidAdjustment)
+	}
+	adjustment, err := s.pendingAdjustment(ctx, orgID, id, userID)
+	if err != nil || adjustment == nil {
+		return nil, err
+	}
+	return s.repo.Reject(ctx, orgID, id, userID, review.Note)
+}
+
+func (s *StockAdjustmentService) pendingAdjustment(ctx context.Context, orgID, id, userID uuid.UUID) (*types.StockAdjustment, error) {
+	adjustment, err := s.repo.FindByID(ctx, orgID, id)
+	if err != nil || adjustment == nil {
+		return nil, err
+	}
+	if adjustment.Status != types.StockAdjustmentStatusPending {
+		return nil, ErrAdjustmentNotPending
+	}
+	if adjustment.RequestedBy == userID {
+		return nil, fmt.Errorf("%w: adjustments must be reviewed by another user", ErrInvalidAdjustment)
+	}
+	return adjustment, nil
+}
+
+// GetReport totals the adjustments applied in a date range by reason, user
+// and period for shrinkage analysis
+func (s *StockAdjustmentService) GetReport(ctx context.Context, filter types.AdjustmentReportFilter) (*types.AdjustmentReport, error) {
+	if filter.Period == "" {
+		filter.Period = "week"
+	}
+	switch filter.Period {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("%w: period must be day, week or month", ErrInvalidAdjustment)
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAdjustment)
+	}
+	if filter.To.Sub(filter.From) > MaxAdjustmentReportDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: report range cannot exceed %d days", ErrInvalidAdjustment, MaxAdjustmentReportDays)
+	}
+
+	report := &types.AdjustmentReport{
+		From:   filter.From,
+		To:     filter.To,
+		Period: filter.Period,
+	}
+
+	totals, err := s.repo.Summarize(ctx, filter, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Total = totals[0]
+	}
+	if report.ByReason, err = s.repo.Summarize(ctx, filter, types.AdjustmentReportByReason); err != nil {
+		return nil, err
+	}
+	if report.ByUser, err = s.repo.Summarize(ctx, filter, types.AdjustmentReportByUser); err != nil {
+		return nil, err
+	}
+	if report.ByPeriod, err = s.repo.Summarize(ctx, filter, types.AdjustmentReportByPeriod); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
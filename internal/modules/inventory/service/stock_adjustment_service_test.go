@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/inventory/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockStockAdjustmentRepository is a mock implementation of StockAdjustmentRepository
+type MockStockAdjustmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockStockAdjustmentRepository) ListReasons(ctx context.Context, orgID uuid.UUID) ([]types.AdjustmentReason, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]types.AdjustmentReason), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) FindReason(ctx context.Context, orgID uuid.UUID, code string) (*types.AdjustmentReason, error) {
+	args := m.Called(ctx, orgID, code)
+	return args.Get(0).(*types.AdjustmentReason), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) SaveReason(ctx context.Context, orgID uuid.UUID, request types.AdjustmentReasonRequest) (*types.AdjustmentReason, error) {
+	args := m.Called(ctx, orgID, request)
+	return args.Get(0).(*types.AdjustmentReason), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) GetApprovalSettings(ctx context.Context, orgID uuid.UUID) (*types.AdjustmentApprovalSettings, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(*types.AdjustmentApprovalSettings), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) SaveApprovalSettings(ctx context.Context, orgID uuid.UUID, settings types.AdjustmentApprovalSettings) error {
+	args := m.Called(ctx, orgID, settings)
+	return args.Error(0)
+}
+
+func (m *MockStockAdjustmentRepository) FindStock(ctx context.Context, orgID, productID, locationID uuid.UUID) (*types.AdjustmentStock, error) {
+	args := m.Called(ctx, orgID, productID, locationID)
+	return args.Get(0).(*types.AdjustmentStock), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) Create(ctx context.Context, adjustment types.StockAdjustment) (*types.StockAdjustment, error) {
+	args := m.Called(ctx, adjustment)
+	if created, ok := args.Get(0).(func(types.StockAdjustment) *types.StockAdjustment); ok {
+		return created(adjustment), args.Error(1)
+	}
+	return args.Get(0).(*types.StockAdjustment), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) FindByID(ctx context.Context, orgID, id uuid.UUID) (*types.StockAdjustment, error) {
+	args := m.Called(ctx, orgID, id)
+	return args.Get(0).(*types.StockAdjustment), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) List(ctx context.Context, filter types.StockAdjustmentFilter) ([]types.StockAdjustment, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]types.StockAdjustment), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) Apply(ctx context.Context, orgID, id uuid.UUID, reviewedBy *uuid.UUID, note string) (*types.StockAdjustment, error) {
+	args := m.Called(ctx, orgID, id, reviewedBy, note)
+	return args.Get(0).(*types.StockAdjustment), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) Reject(ctx context.Context, orgID, id, reviewedBy uuid.UUID, note string) (*types.StockAdjustment, error) {
+	args := m.Called(ctx, orgID, id, reviewedBy, note)
+	return args.Get(0).(*types.StockAdjustment), args.Error(1)
+}
+
+func (m *MockStockAdjustmentRepository) Summarize(ctx context.Context, filter types.AdjustmentReportFilter, group types.AdjustmentReportGroup) ([]types.AdjustmentTotal, error) {
+	args := m.Called(ctx, filter, group)
+	return args.Get(0).([]types.AdjustmentTotal), args.Error(1)
+}
+
+func floatValue(v float64) *float64 {
+	return &v
+}
+
+func TestStockAdjustmentService_RequestAdjustment(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	productID := uuid.New()
+	locationID := uuid.New()
+
+	tests := []struct {
+		name       string
+		request    types.StockAdjustmentRequest
+		settings   *types.AdjustmentApprovalSettings
+		wantQty    float64
+		wantValue  float64
+		wantStatus types.StockAdjustmentStatus
+	}{
+		{
+			name:       "small loss is applied",
+			request:    types.StockAdjustmentRequest{Quantity: floatValue(-2), ReasonCode: "damage"},
+			wantQty:    -2,
+			wantValue:  -25,
+			wantStatus: types.StockAdjustmentStatusApplied,
+		},
+		{
+			name:       "counted quantity above threshold waits for approval",
+			request:    types.StockAdjustmentRequest{NewQuantity: floatValue(0), ReasonCode: "damage"},
+			wantQty:    -50,
+			wantValue:  -625,
+			wantStatus: types.StockAdjustmentStatusPending,
+		},
+		{
+			name:       "reason threshold overrides the default",
+			request:    types.StockAdjustmentRequest{Quantity: floatValue(-1), ReasonCode: "damage"},
+			settings:   &types.AdjustmentApprovalSettings{Threshold: 1000, ReasonThresholds: map[string]float64{"damage": 0}},
+			wantQty:    -1,
+			wantValue:  -12.5,
+			wantStatus: types.StockAdjustmentStatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockStockAdjustmentRepository)
+			repo.On("FindReason", ctx, orgID, "damage").Return(&types.AdjustmentReason{Code: "damage", Active: true}, nil)
+			repo.On("FindStock", ctx, orgID, productID, locationID).Return(&types.AdjustmentStock{OnHand: 50, UnitCost: 12.5}, nil)
+			repo.On("GetApprovalSettings", ctx, orgID).Return(tt.settings, nil)
+			repo.On("Create", ctx, mock.Anything).Return(func(a types.StockAdjustment) *types.StockAdjustment {
+				return &a
+			}, nil)
+
+			service := NewStockAdjustmentService(repo)
+			tt.request.ProductID = productID
+			tt.request.LocationID = locationID
+
+			adjustment, err := service.RequestAdjustment(ctx, orgID, userID, tt.request)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantQty, adjustment.Quantity)
+			assert.Equal(t, tt.wantValue, adjustment.Value)
+			assert.Equal(t, tt.wantStatus, adjustment.Status)
+			assert.Equal(t, 50.0, adjustment.QuantityBefore)
+			assert.Equal(t, userID, adjustment.RequestedBy)
+		})
+	}
+}
+
+func TestStockAdjustmentService_RequestAdjustment_ValidationErrors(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	productID := uuid.New()
+	locationID := uuid.New()
+
+	repo := new(MockStockAdjustmentRepository)
+	repo.On("FindReason", ctx, orgID, "theft").Return(&types.AdjustmentReason{Code: "theft", RequiresNote: true, Active: true}, nil)
+	repo.On("FindReason", ctx, orgID, "retired").Return(&types.AdjustmentReason{Code: "retired"}, nil)
+	repo.On("FindReason", ctx, orgID, "damage").Return(&types.AdjustmentReason{Code: "damage", Active: true}, nil)
+	repo.On("FindStock", ctx, orgID, productID, locationID).Return(&types.AdjustmentStock{OnHand: 5, UnitCost: 1}, nil)
+	service := NewStockAdjustmentService(repo)
+
+	tests := []struct {
+		name    string
+		request types.StockAdjustmentRequest
+	}{
+		{"no reason", types.StockAdjustmentRequest{Quantity: floatValue(-1)}},
+		{"inactive reason", types.StockAdjustmentRequest{Quantity: floatValue(-1), ReasonCode: "retired"}},
+		{"missing note", types.StockAdjustmentRequest{Quantity: floatValue(-1), ReasonCode: "theft"}},
+		{"both quantities", types.StockAdjustmentRequest{Quantity: floatValue(-1), NewQuantity: floatValue(4), ReasonCode: "damage"}},
+		{"no change", types.StockAdjustmentRequest{NewQuantity: floatValue(5), ReasonCode: "damage"}},
+		{"more than on hand", types.StockAdjustmentRequest{Quantity: floatValue(-6), ReasonCode: "damage"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.ProductID = productID
+			tt.request.LocationID = locationID
+
+			_, err := service.RequestAdjustment(ctx, orgID, uuid.New(), tt.request)
+			assert.ErrorIs(t, err, ErrInvalidAdjustment)
+		})
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestStockAdjustmentService_ApproveAdjustment(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	requester := uuid.New()
+	approver := uuid.New()
+	pending := &types.StockAdjustment{ID: uuid.New(), Status: types.StockAdjustmentStatusPending, RequestedBy: requester}
+	applied := &types.StockAdjustment{ID: uuid.New(), Status: types.StockAdjustmentStatusApplied, RequestedBy: requester}
+
+	repo := new(MockStockAdjustmentRepository)
+	repo.On("FindByID", ctx, orgID, pending.ID).Return(pending, nil)
+	repo.On("FindByID", ctx, orgID, applied.ID).Return(applied, nil)
+	repo.On("Apply", ctx, orgID, pending.ID, &approver, "checked").Return(&types.StockAdjustment{ID: pending.ID, Status: types.StockAdjustmentStatusApplied}, nil)
+	service := NewStockAdjustmentService(repo)
+
+	_, err := service.ApproveAdjustment(ctx, orgID, pending.ID, requester, types.StockAdjustmentReview{})
+	assert.ErrorIs(t, err, ErrInvalidAdjustment, "requesters cannot approve their own adjustments")
+
+	_, err = service.ApproveAdjustment(ctx, orgID, applied.ID, approver, types.StockAdjustmentReview{})
+	assert.ErrorIs(t, err, ErrAdjustmentNotPending)
+
+	adjustment, err := service.ApproveAdjustment(ctx, orgID, pending.ID, approver, types.StockAdjustmentReview{Note: " checked "})
+	require.NoError(t, err)
+	assert.Equal(t, types.StockAdjustmentStatusApplied, adjustment.Status)
+	repo.AssertExpectations(t)
+}
+
+func TestStockAdjustmentService_GetReport_ValidationErrors(t *testing.T) {
+	service := NewStockAdjustmentService(new(MockStockAdjustmentRepository))
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		filter types.AdjustmentReportFilter
+	}{
+		{"unknown period", types.AdjustmentReportFilter{From: now.AddDate(0, 0, -7), To: now, Period: "year"}},
+		{"empty range", types.AdjustmentReportFilter{From: now, To: now}},
+		{"range too long", types.AdjustmentReportFilter{From: now.AddDate(-2, 0, 0), To: now}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetReport(context.Background(), tt.filter)
+			assert.ErrorIs(t, err, ErrInvalidAdjustment)
+		})
+	}
+}
@@ -10,11 +10,11 @@ import (
 
 // StockMoveService handles business logic for stock moves
 type StockMoveService struct {
-	repo   *repository.StockMoveRepository
+	repo repository.StockMoveRepository
 }
 
 // NewStockMoveService creates a new StockMoveService
-func NewStockMoveService(repo *repository.StockMoveRepository) *StockMoveService {
+func NewStockMoveService(repo repository.StockMoveRepository) *StockMoveService {
 	return &StockMoveService{
 		repo: repo,
 	}
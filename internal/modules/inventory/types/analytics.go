@@ -139,4 +139,5 @@ type AnalyticsSummary struct {
 	DeadStockPercentage     float64   `json:"dead_stock_percentage" db:"dead_stock_percentage"`
 	ProductsNeedingReorder  int       `json:"products_needing_reorder" db:"products_needing_reorder"`
 	ProductsBelowSafetyStock int      `json:"products_below_safety_stock" db:"products_below_safety_stock"`
+	GeneratedAt             *time.Time `json:"generated_at,omitempty" db:"-"` // Set when served from the analytics cache
 }
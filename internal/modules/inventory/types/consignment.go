@@ -0,0 +1,179 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsignmentBillingPolicy is when consumed consignment stock is billed
+type ConsignmentBillingPolicy string
+
+const (
+	// ConsignmentBillOnConsumption drafts a vendor bill for every consumption
+	ConsignmentBillOnConsumption ConsignmentBillingPolicy = "consumption"
+	// ConsignmentBillManually leaves consumptions unbilled until the agreement is billed
+	ConsignmentBillManually ConsignmentBillingPolicy = "manual"
+)
+
+// IsValid reports whether the billing policy is supported
+func (p ConsignmentBillingPolicy) IsValid() bool {
+	return p == ConsignmentBillOnConsumption || p == ConsignmentBillManually
+}
+
+// ConsignmentAgreement lists the products a vendor keeps on consignment with
+// the organization and the price the organization pays once it consumes them.
+// An organization has one agreement per vendor.
+type ConsignmentAgreement struct {
+	ID             uuid.UUID                `json:"id" db:"id"`
+	OrganizationID uuid.UUID                `json:"organization_id" db:"organization_id"`
+	VendorID       uuid.UUID                `json:"vendor_id" db:"vendor_id"`
+	VendorName     string                   `json:"vendor_name,omitempty" db:"vendor_name"`
+	Reference      string                   `json:"reference,omitempty" db:"reference"`
+	BillingPolicy  ConsignmentBillingPolicy `json:"billing_policy" db:"billing_policy"`
+	Active         bool                     `json:"active" db:"active"`
+	Items          []ConsignmentItem        `json:"items"`
+	CreatedAt      time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+// ConsignmentItem is the price of a consigned product
+type ConsignmentItem struct {
+	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName string    `json:"product_name,omitempty" db:"product_name"`
+	UnitPrice   float64   `json:"unit_price" db:"unit_price"`
+}
+
+// ConsignmentAgreementRequest creates or replaces the agreement with a vendor
+type ConsignmentAgreementRequest struct {
+	VendorID      uuid.UUID                `json:"vendor_id"`
+	Reference     string                   `json:"reference"`
+	BillingPolicy ConsignmentBillingPolicy `json:"billing_policy"`
+	Active        *bool                    `json:"active,omitempty"`
+	Items         []ConsignmentItem        `json:"items"`
+}
+
+// ConsignmentReceiptRequest receives stock that stays the vendor's property
+type ConsignmentReceiptRequest struct {
+	VendorID   uuid.UUID  `json:"vendor_id"`
+	ProductID  uuid.UUID  `json:"product_id"`
+	LocationID uuid.UUID  `json:"location_id"`
+	LotID      *uuid.UUID `json:"lot_id,omitempty"`
+	Quantity   float64    `json:"quantity"`
+	Reference  string     `json:"reference"`
+}
+
+// ConsignmentReceipt is consigned stock received from a vendor
+type ConsignmentReceipt struct {
+	StockMoveID uuid.UUID  `json:"stock_move_id"`
+	VendorID    uuid.UUID  `json:"vendor_id"`
+	ProductID   uuid.UUID  `json:"product_id"`
+	LocationID  uuid.UUID  `json:"location_id"`
+	LotID       *uuid.UUID `json:"lot_id,omitempty"`
+	Quantity    float64    `json:"quantity"`
+	Consigned   float64    `json:"consigned_quantity"`
+}
+
+// ConsignmentConsumptionRequest consumes consigned stock, transferring its
+// ownership from the vendor to the organization
+type ConsignmentConsumptionRequest struct {
+	VendorID   uuid.UUID  `json:"vendor_id"`
+	ProductID  uuid.UUID  `json:"product_id"`
+	LocationID uuid.UUID  `json:"location_id"`
+	LotID      *uuid.UUID `json:"lot_id,omitempty"`
+	Quantity   float64    `json:"quantity"`
+	Reference  string     `json:"reference"`
+}
+
+// ConsignmentConsumption is consigned stock the organization consumed and
+// owes the vendor for. InvoiceID is the draft vendor bill it was billed on.
+type ConsignmentConsumption struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	AgreementID    uuid.UUID  `json:"agreement_id" db:"agreement_id"`
+	VendorID       uuid.UUID  `json:"vendor_id" db:"vendor_id"`
+	ProductID      uuid.UUID  `json:"product_id" db:"product_id"`
+	ProductName    string     `json:"product_name,omitempty" db:"product_name"`
+	LocationID     uuid.UUID  `json:"location_id" db:"location_id"`
+	LotID          *uuid.UUID `json:"lot_id,omitempty" db:"lot_id"`
+	Quantity       float64    `json:"quantity" db:"quantity"`
+	UnitPrice      float64    `json:"unit_price" db:"unit_price"`
+	Amount         float64    `json:"amount" db:"amount"`
+	Reference      string     `json:"reference,omitempty" db:"reference"`
+	ConsumedBy     uuid.UUID  `json:"consumed_by" db:"consumed_by"`
+	ConsumedAt     time.Time  `json:"consumed_at" db:"consumed_at"`
+	InvoiceID      *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+	BilledAt       *time.Time `json:"billed_at,omitempty" db:"billed_at"`
+	// BillingError says why a consumption billed on consumption is still unbilled
+	BillingError string `json:"billing_error,omitempty" db:"-"`
+}
+
+// ConsignmentConsumptionFilter selects consumptions to list
+type ConsignmentConsumptionFilter struct {
+	OrganizationID uuid.UUID
+	AgreementID    *uuid.UUID
+	Unbilled       bool
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// ConsignmentBill is a draft vendor bill for consumed consignment stock
+type ConsignmentBill struct {
+	InvoiceID    uuid.UUID `json:"invoice_id"`
+	AgreementID  uuid.UUID `json:"agreement_id"`
+	VendorID     uuid.UUID `json:"vendor_id"`
+	Consumptions int       `json:"consumptions"`
+	AmountTotal  float64   `json:"amount_total"`
+}
+
+// StockOwnership is the quantity of a product at a location and lot owned
+// by the organization, or consigned by a vendor when OwnerID is set.
+// Consigned stock is excluded from inventory valuation.
+type StockOwnership struct {
+	ProductID    uuid.UUID  `json:"product_id"`
+	ProductName  string     `json:"product_name"`
+	LocationID   uuid.UUID  `json:"location_id"`
+	LocationName string     `json:"location_name"`
+	LotID        *uuid.UUID `json:"lot_id,omitempty"`
+	LotName      string     `json:"lot_name,omitempty"`
+	OwnerID      *uuid.UUID `json:"owner_id,omitempty"`
+	OwnerName    string     `json:"owner_name,omitempty"`
+	Consigned    bool       `json:"consigned"`
+	Quantity     float64    `json:"quantity"`
+}
+
+// StockOwnershipFilter selects the stock to break down by owner
+type StockOwnershipFilter struct {
+	OrganizationID uuid.UUID
+	ProductID      *uuid.UUID
+	LocationID     *uuid.UUID
+	OwnerID        *uuid.UUID
+	ConsignedOnly  bool
+}
+
+// ConsignmentStatementLine is the consignment activity of one product
+type ConsignmentStatementLine struct {
+	ProductID        uuid.UUID `json:"product_id"`
+	ProductName      string    `json:"product_name"`
+	ReceivedQuantity float64   `json:"received_quantity"`
+	ConsumedQuantity float64   `json:"consumed_quantity"`
+	ConsumedAmount   float64   `json:"consumed_amount"`
+	BilledAmount     float64   `json:"billed_amount"`
+	OnHandQuantity   float64   `json:"on_hand_quantity"`
+}
+
+// ConsignmentStatement reports to a vendor what was received and consumed
+// in a date range and what of its stock is still on hand
+type ConsignmentStatement struct {
+	AgreementID    uuid.UUID                  `json:"agreement_id"`
+	VendorID       uuid.UUID                  `json:"vendor_id"`
+	VendorName     string                     `json:"vendor_name"`
+	From           time.Time                  `json:"from"`
+	To             time.Time                  `json:"to"`
+	Lines          []ConsignmentStatementLine `json:"lines"`
+	ConsumedAmount float64                    `json:"consumed_amount"`
+	BilledAmount   float64                    `json:"billed_amount"`
+	UnbilledAmount float64                    `json:"unbilled_amount"`
+}
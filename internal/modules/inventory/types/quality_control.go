@@ -101,6 +101,8 @@ type QualityControlInspectionItem struct {
 	Description     string `json:"description" db:"description"`
 	Result          string `json:"result" db:"result"` // "pass", "fail", "na"
 	Notes           *string `json:"notes,omitempty" db:"notes"`
+	// EquipmentID is the calibrated instrument the item was measured with
+	EquipmentID     *uuid.UUID `json:"equipment_id,omitempty" db:"equipment_id"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
 
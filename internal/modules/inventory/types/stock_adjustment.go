@@ -0,0 +1,154 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdjustmentReason is a reason code manual stock adjustments must carry.
+// System reasons have no organization and are available to every organization.
+type AdjustmentReason struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty" db:"organization_id"`
+	Code           string     `json:"code" db:"code"`
+	Name           string     `json:"name" db:"name"`
+	Description    string     `json:"description,omitempty" db:"description"`
+	RequiresNote   bool       `json:"requires_note" db:"requires_note"`
+	Active         bool       `json:"active" db:"active"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AdjustmentReasonRequest creates or updates an organization's reason code
+type AdjustmentReasonRequest struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	RequiresNote bool   `json:"requires_note"`
+	Active       *bool  `json:"active,omitempty"`
+}
+
+// AdjustmentApprovalSettings are stored under the
+// "inventory_adjustment_approval" key of the organization's settings.
+// Adjustments whose absolute value reaches the threshold wait for approval.
+type AdjustmentApprovalSettings struct {
+	Threshold float64 `json:"threshold"`
+	// ReasonThresholds overrides the threshold per reason code, e.g. 0 to
+	// have every theft reviewed
+	ReasonThresholds map[string]float64 `json:"reason_thresholds,omitempty"`
+}
+
+// StockAdjustmentStatus is where a stock adjustment is in its approval
+type StockAdjustmentStatus string
+
+const (
+	StockAdjustmentStatusPending  StockAdjustmentStatus = "pending"
+	StockAdjustmentStatusApplied  StockAdjustmentStatus = "applied"
+	StockAdjustmentStatusRejected StockAdjustmentStatus = "rejected"
+)
+
+// StockAdjustment is a manual correction of the quantity on hand of a
+// product at a location. Quantity is signed: negative for losses. Value is
+// Quantity at the product's cost when the adjustment was requested.
+type StockAdjustment struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	OrganizationID uuid.UUID             `json:"organization_id" db:"organization_id"`
+	ProductID      uuid.UUID             `json:"product_id" db:"product_id"`
+	ProductName    string                `json:"product_name,omitempty" db:"product_name"`
+	LocationID     uuid.UUID             `json:"location_id" db:"location_id"`
+	LocationName   string                `json:"location_name,omitempty" db:"location_name"`
+	ReasonCode     string                `json:"reason_code" db:"reason_code"`
+	Note           string                `json:"note,omitempty" db:"note"`
+	QuantityBefore float64               `json:"quantity_before" db:"quantity_before"`
+	Quantity       float64               `json:"quantity" db:"quantity"`
+	UnitCost       float64               `json:"unit_cost" db:"unit_cost"`
+	Value          float64               `json:"value" db:"value"`
+	Status         StockAdjustmentStatus `json:"status" db:"status"`
+	RequestedBy    uuid.UUID             `json:"requested_by" db:"requested_by"`
+	RequestedAt    time.Time             `json:"requested_at" db:"requested_at"`
+	ReviewedBy     *uuid.UUID            `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt     *time.Time            `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNote     string                `json:"review_note,omitempty" db:"review_note"`
+	AppliedAt      *time.Time            `json:"applied_at,omitempty" db:"applied_at"`
+	StockMoveID    *uuid.UUID            `json:"stock_move_id,omitempty" db:"stock_move_id"`
+}
+
+// StockAdjustmentRequest adjusts stock either by a signed Quantity or to a
+// counted NewQuantity; exactly one of them must be set
+type StockAdjustmentRequest struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	LocationID  uuid.UUID `json:"location_id"`
+	Quantity    *float64  `json:"quantity,omitempty"`
+	NewQuantity *float64  `json:"new_quantity,omitempty"`
+	ReasonCode  string    `json:"reason_code"`
+	Note        string    `json:"note"`
+}
+
+// AdjustmentStock is the untracked stock of a product at a location that an
+// adjustment is requested against
+type AdjustmentStock struct {
+	ProductName  string
+	LocationName string
+	OnHand       float64
+	UnitCost     float64
+}
+
+// StockAdjustmentReview approves or rejects a pending adjustment
+type StockAdjustmentReview struct {
+	Note string `json:"note"`
+}
+
+// StockAdjustmentFilter selects adjustments to list
+type StockAdjustmentFilter struct {
+	OrganizationID uuid.UUID
+	Status         StockAdjustmentStatus
+	ReasonCode     string
+	ProductID      *uuid.UUID
+	LocationID     *uuid.UUID
+	Limit          int
+	Offset         int
+}
+
+// AdjustmentReportGroup is what an adjustment report total is grouped by
+type AdjustmentReportGroup string
+
+const (
+	AdjustmentReportByReason AdjustmentReportGroup = "reason"
+	AdjustmentReportByUser   AdjustmentReportGroup = "user"
+	AdjustmentReportByPeriod AdjustmentReportGroup = "period"
+)
+
+// AdjustmentReportFilter selects the applied adjustments of a report.
+// Period is day, week or month.
+type AdjustmentReportFilter struct {
+	OrganizationID uuid.UUID
+	From           time.Time
+	To             time.Time
+	Period         string
+	LocationID     *uuid.UUID
+}
+
+// AdjustmentTotal sums applied adjustments. Gains and Losses are the value
+// of the stock added and removed; grouped by reason, the losses of damage
+// and theft are the shrinkage.
+type AdjustmentTotal struct {
+	Key         string  `json:"key"`
+	Label       string  `json:"label"`
+	Adjustments int     `json:"adjustments"`
+	QuantityIn  float64 `json:"quantity_in"`
+	QuantityOut float64 `json:"quantity_out"`
+	Gains       float64 `json:"gains"`
+	Losses      float64 `json:"losses"`
+	NetValue    float64 `json:"net_value"`
+}
+
+// AdjustmentReport breaks applied adjustments down for shrinkage analysis
+type AdjustmentReport struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Period   string            `json:"period"`
+	Total    AdjustmentTotal   `json:"total"`
+	ByReason []AdjustmentTotal `json:"by_reason"`
+	ByUser   []AdjustmentTotal `json:"by_user"`
+	ByPeriod []AdjustmentTotal `json:"by_period"`
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// UsageHandler handles HTTP requests for an organization's metered usage
+// and the billing events reported from it
+type UsageHandler struct {
+	service *service.UsageService
+}
+
+func NewUsageHandler(service *service.UsageService) *UsageHandler {
+	return &UsageHandler{service: service}
+}
+
+func (h *UsageHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/usage", h.GetUsage)
+	router.GET("/api/v1/admin/usage/billing-events", h.ListBillingEvents)
+	router.POST("/api/v1/admin/usage/billing-events/:id/delivered", h.MarkDelivered)
+}
+
+// writeUsageError maps service errors to HTTP statuses
+func writeUsageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, types.ErrInvalidUsage):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, types.ErrForbidden), strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, types.ErrBillingEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseDay parses an optional YYYY-MM-DD query parameter
+func parseDay(r *http.Request, name string) (time.Time, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	day, err := time.Parse("2006-01-02", raw)
+	return day, err == nil
+}
+
+// GetUsage handles GET /api/v1/usage?from=&to=, defaulting to the month so far
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	from, ok := parseDay(r, "from")
+	if !ok {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, ok := parseDay(r, "to")
+	if !ok {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.GetUsage(r.Context(), authCtx.OrganizationID, from, to)
+	if err != nil {
+		writeUsageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// ListBillingEvents handles GET /api/v1/admin/usage/billing-events?status=&organization_id=&limit=
+func (h *UsageHandler) ListBillingEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.BillingEventFilter{Status: types.BillingEventStatus(query.Get("status"))}
+	if raw := query.Get("organization_id"); raw != "" {
+		orgID, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+		filter.OrganizationID = &orgID
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	events, err := h.service.ListBillingEvents(r.Context(), filter)
+	if err != nil {
+		writeUsageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// MarkDelivered handles POST /api/v1/admin/usage/billing-events/:id/delivered
+func (h *UsageHandler) MarkDelivered(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid billing event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DeliveredRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	event, err := h.service.MarkDelivered(r.Context(), id, req)
+	if err != nil {
+		writeUsageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeUsageCloseDay = "usage.close_day"
+
+// finalFlushTimeout bounds saving the counted usage on shutdown
+const finalFlushTimeout = 5 * time.Second
+
+// FlushRunner adds the usage counted by this instance to the daily counters
+// in the background. Every instance runs one for its own counts.
+type FlushRunner struct {
+	usageService *service.UsageService
+	logger       *slog.Logger
+}
+
+func NewFlushRunner(usageService *service.UsageService, logger *slog.Logger) *FlushRunner {
+	return &FlushRunner{
+		usageService: usageService,
+		logger:       logger,
+	}
+}
+
+// Start flushes every flush interval, and once more on shutdown, until ctx
+// is cancelled
+func (r *FlushRunner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(service.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+				r.flush(flushCtx)
+				cancel()
+				return
+			case <-ticker.C:
+				r.flush(ctx)
+			}
+		}
+	}()
+}
+
+func (r *FlushRunner) flush(ctx context.Context) {
+	if err := r.usageService.Flush(ctx); err != nil {
+		r.logger.Error("Usage flush failed", "error", err)
+	}
+}
+
+// UsageCloseDayJobHandler handles queued jobs closing the previous day's usage
+type UsageCloseDayJobHandler struct {
+	usageService *service.UsageService
+	logger       *slog.Logger
+}
+
+func NewUsageCloseDayJobHandler(usageService *service.UsageService, logger *slog.Logger) *UsageCloseDayJobHandler {
+	return &UsageCloseDayJobHandler{
+		usageService: usageService,
+		logger:       logger,
+	}
+}
+
+// Handle closes the previous day and reports it as billing events
+func (h *UsageCloseDayJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	events, err := h.usageService.CloseDay(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to close usage day: %w", err)
+	}
+	h.logger.Info("Usage day closed", "billing_events", len(events))
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *UsageCloseDayJobHandler) JobType() string {
+	return JobTypeUsageCloseDay
+}
+
+// Scheduler closes the previous day's usage once a day, at midnight UTC
+type Scheduler struct {
+	handler     *UsageCloseDayJobHandler
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *UsageCloseDayJobHandler, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start closes a day every close interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeUsageCloseDay, service.CloseInterval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeUsageCloseDay, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled usage close failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule usage close", "error", err)
+	}
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/types"
+
+	"github.com/google/uuid"
+)
+
+// UsageRepo defines the interface for usage metering repository operations
+type UsageRepo interface {
+	AddUsage(ctx context.Context, increments []types.UsageIncrement) error
+	AddActiveUsers(ctx context.Context, users []types.ActiveUser) error
+	CloseDay(ctx context.Context, day time.Time) error
+	CreateBillingEvents(ctx context.Context, day time.Time) ([]types.BillingEvent, error)
+	ListUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.DailyUsage, error)
+	ListBillingEvents(ctx context.Context, filter types.BillingEventFilter) ([]types.BillingEvent, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, externalID string) (*types.BillingEvent, error)
+}
+
+// UsageRepository persists daily usage and the billing events reported from it
+type UsageRepository struct {
+	db *sql.DB
+}
+
+// Ensure UsageRepository implements UsageRepo interface
+var _ UsageRepo = &UsageRepository{}
+
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// storedRecordTables are the records counted into stored_records
+var storedRecordTables = []string{"contacts", "leads", "products", "sales_orders", "invoices", "delivery_shipments"}
+
+// AddUsage adds the increments to the daily counters
+func (r *UsageRepository) AddUsage(ctx context.Context, increments []types.UsageIncrement) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	var values strings.Builder
+	args := make([]interface{}, 0, 4*len(increments))
+	for i, inc := range increments {
+		if i > 0 {
+			values.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&values, "($%d, $%d::date, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, inc.OrganizationID, inc.Day, string(inc.Metric), inc.Quantity)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO usage_daily (organization_id, day, metric, quantity)
+		VALUES `+values.String()+`
+		ON CONFLICT (organization_id, day, metric)
+		DO UPDATE SET quantity = usage_daily.quantity + EXCLUDED.quantity, updated_at = now()`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+	return nil
+}
+
+// AddActiveUsers records users seen making API calls
+func (r *UsageRepository) AddActiveUsers(ctx context.Context, users []types.ActiveUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	var values strings.Builder
+	args := make([]interface{}, 0, 3*len(users))
+	for i, user := range users {
+		if i > 0 {
+			values.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&values, "($%d, $%d::date, $%d)", n+1, n+2, n+3)
+		args = append(args, user.OrganizationID, user.Day, user.UserID)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO usage_active_users (organization_id, day, user_id)
+		VALUES `+values.String()+`
+		ON CONFLICT DO NOTHING`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add active users: %w", err)
+	}
+	return nil
+}
+
+// CloseDay sets the day's gauges and the shipments tracked from the records
+// themselves: stored_records counts the organization's records not deleted,
+// active_users the users seen that day and shipments_tracked the shipments
+// created that day
+func (r *UsageRepository) CloseDay(ctx context.Context, day time.Time) error {
+	var stored strings.Builder
+	for i, table := range storedRecordTables {
+		if i > 0 {
+			stored.WriteString(" UNION ALL ")
+		}
+		fmt.Fprintf(&stored, "SELECT organization_id FROM %s WHERE deleted_at IS NULL", table)
+	}
+
+	query := `
+		INSERT INTO usage_daily (organization_id, day, metric, quantity)
+		SELECT organization_id, $1::date, metric, quantity FROM (
+			SELECT organization_id, 'stored_records' AS metric, COUNT(*) AS quantity
+			FROM (` + stored.String() + `) records
+			GROUP BY organization_id
+			UNION ALL
+			SELECT organization_id, 'active_users', COUNT(*)
+			FROM usage_active_users
+			WHERE day = $1::date
+			GROUP BY organization_id
+			UNION ALL
+			SELECT organization_id, 'shipments_tracked', COUNT(*)
+			FROM delivery_shipments
+			WHERE created_at >= $1::date AND created_at < $1::date + 1
+			GROUP BY organization_id
+		) measured
+		ON CONFLICT (organization_id, day, metric)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = now()`
+
+	if _, err := r.db.ExecContext(ctx, query, day); err != nil {
+		return fmt.Errorf("failed to close usage day: %w", err)
+	}
+	return nil
+}
+
+const billingEventColumns = `id, organization_id, metric, day, quantity, action, idempotency_key, status,
+	external_id, created_at, delivered_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBillingEvent(row rowScanner) (*types.BillingEvent, error) {
+	var event types.BillingEvent
+	err := row.Scan(&event.ID, &event.OrganizationID, &event.Metric, &event.Day, &event.Quantity,
+		&event.Action, &event.IdempotencyKey, &event.Status, &event.ExternalID, &event.CreatedAt, &event.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *UsageRepository) queryBillingEvents(ctx context.Context, query string, args ...interface{}) ([]types.BillingEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []types.BillingEvent{}
+	for rows.Next() {
+		event, err := scanBillingEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan billing event: %w", err)
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating billing events: %w", err)
+	}
+	return events, nil
+}
+
+// CreateBillingEvents reports the day's nonzero usage as billing events and
+// returns those not reported before. The idempotency key is the
+// organization, metric and day, so closing a day twice reports it once.
+func (r *UsageRepository) CreateBillingEvents(ctx context.Context, day time.Time) ([]types.BillingEvent, error) {
+	return r.queryBillingEvents(ctx, `
+		INSERT INTO usage_billing_events (organization_id, metric, day, quantity, action, idempotency_key)
+		SELECT organization_id, metric, day, quantity,
+		       CASE WHEN metric IN ($2, $3) THEN 'set' ELSE 'increment' END,
+		       organization_id::text || ':' || metric || ':' || to_char(day, 'YYYY-MM-DD')
+		FROM usage_daily
+		WHERE day = $1::date AND quantity > 0
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING `+billingEventColumns,
+		day, string(types.MetricStoredRecords), string(types.MetricActiveUsers),
+	)
+}
+
+// ListUsage returns an organization's daily usage between two days, inclusive
+func (r *UsageRepository) ListUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.DailyUsage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT organization_id, day, metric, quantity, updated_at
+		FROM usage_daily
+		WHERE organization_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY metric, day`,
+		orgID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []types.DailyUsage{}
+	for rows.Next() {
+		var day types.DailyUsage
+		if err := rows.Scan(&day.OrganizationID, &day.Day, &day.Metric, &day.Quantity, &day.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		usage = append(usage, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ListBillingEvents returns billing events, oldest first
+func (r *UsageRepository) ListBillingEvents(ctx context.Context, filter types.BillingEventFilter) ([]types.BillingEvent, error) {
+	query := `SELECT ` + billingEventColumns + ` FROM usage_billing_events WHERE 1=1`
+	args := []interface{}{}
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.OrganizationID != nil {
+		args = append(args, *filter.OrganizationID)
+		query += fmt.Sprintf(" AND organization_id = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", len(args))
+
+	return r.queryBillingEvents(ctx, query, args...)
+}
+
+// MarkDelivered records that the billing provider accepted an event. Marking
+// a delivered event again keeps its first delivery.
+func (r *UsageRepository) MarkDelivered(ctx context.Context, id uuid.UUID, externalID string) (*types.BillingEvent, error) {
+	event, err := scanBillingEvent(r.db.QueryRowContext(ctx, `
+		UPDATE usage_billing_events
+		SET status = 'delivered',
+		    external_id = COALESCE(external_id, NULLIF($2, '')),
+		    delivered_at = COALESCE(delivered_at, now())
+		WHERE id = $1
+		RETURNING `+billingEventColumns,
+		id, externalID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, types.ErrBillingEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark billing event delivered: %w", err)
+	}
+	return event, nil
+}
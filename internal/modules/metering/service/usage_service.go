@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// FlushInterval is how often counted usage is added to the daily counters
+	FlushInterval = 30 * time.Second
+	// CloseInterval is how often the previous day is closed and reported
+	CloseInterval = 24 * time.Hour
+	// EventBillingUsageReported is published with the billing events of a
+	// closed day
+	EventBillingUsageReported = "billing.usage_reported"
+	// MaxUsageDays bounds the days of a usage query
+	MaxUsageDays = 366
+	// DefaultBillingEventLimit and MaxBillingEventLimit bound billing event pages
+	DefaultBillingEventLimit = 100
+	MaxBillingEventLimit     = 1000
+)
+
+// Publisher publishes the billing events of closed days
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, payload interface{}) error
+}
+
+// usageKey is a counter of an organization for a day
+type usageKey struct {
+	orgID  uuid.UUID
+	day    time.Time
+	metric types.Metric
+}
+
+// Ensure UsageService implements the metering reader
+var _ metering.Reader = &UsageService{}
+
+// UsageService meters the API calls, active users and emails of each
+// organization, closes each day with its stored records and tracked
+// shipments, and reports the day as billing events for metered billing
+type UsageService struct {
+	repo        repository.UsageRepo
+	authService auth.LegacyAuthService
+	publisher   Publisher
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu      sync.Mutex
+	counts  map[usageKey]int64
+	users   map[types.ActiveUser]struct{}
+	flushMu sync.Mutex
+}
+
+func NewUsageService(repo repository.UsageRepo, authService auth.LegacyAuthService, publisher Publisher, logger *slog.Logger) *UsageService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &UsageService{
+		repo:        repo,
+		authService: authService,
+		publisher:   publisher,
+		logger:      logger,
+		now:         time.Now,
+		counts:      make(map[usageKey]int64),
+		users:       make(map[types.ActiveUser]struct{}),
+	}
+}
+
+// day returns the UTC day an instant falls on
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Record counts usage of a counter metric for an organization today
+func (s *UsageService) Record(orgID uuid.UUID, metric types.Metric, quantity int64) {
+	if orgID == uuid.Nil || quantity <= 0 || metric.IsGauge() {
+		return
+	}
+	key := usageKey{orgID: orgID, day: day(s.now()), metric: metric}
+	s.mu.Lock()
+	s.counts[key] += quantity
+	s.mu.Unlock()
+}
+
+// RecordActiveUser notes a user of an organization as active today
+func (s *UsageService) RecordActiveUser(orgID, userID uuid.UUID) {
+	if orgID == uuid.Nil || userID == uuid.Nil {
+		return
+	}
+	user := types.ActiveUser{OrganizationID: orgID, Day: day(s.now()), UserID: userID}
+	s.mu.Lock()
+	s.users[user] = struct{}{}
+	s.mu.Unlock()
+}
+
+// Middleware counts the authenticated requests passing through next as API
+// calls of their organization and their users as active
+func (s *UsageService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if r.Method == http.MethodOptions {
+			return
+		}
+		authCtx, err := auth.FromContext(r.Context())
+		if err != nil || authCtx.IsSandbox {
+			return
+		}
+		s.Record(authCtx.OrganizationID, types.MetricAPICalls, 1)
+		s.RecordActiveUser(authCtx.OrganizationID, authCtx.UserID)
+	})
+}
+
+// Flush adds the usage counted since the last flush to the daily counters.
+// Usage that fails to save is kept for the next flush.
+func (s *UsageService) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	counts, users := s.counts, s.users
+	s.counts = make(map[usageKey]int64)
+	s.users = make(map[types.ActiveUser]struct{})
+	s.mu.Unlock()
+
+	increments := make([]types.UsageIncrement, 0, len(counts))
+	for key, quantity := range counts {
+		increments = append(increments, types.UsageIncrement{OrganizationID: key.orgID, Day: key.day, Metric: key.metric, Quantity: quantity})
+	}
+	active := make([]types.ActiveUser, 0, len(users))
+	for user := range users {
+		active = append(active, user)
+	}
+
+	if err := s.repo.AddUsage(ctx, increments); err != nil {
+		s.requeue(counts, users)
+		return err
+	}
+	if err := s.repo.AddActiveUsers(ctx, active); err != nil {
+		s.requeue(nil, users)
+		return err
+	}
+	return nil
+}
+
+// requeue puts back usage a flush failed to save
+func (s *UsageService) requeue(counts map[usageKey]int64, users map[types.ActiveUser]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, quantity := range counts {
+		s.counts[key] += quantity
+	}
+	for user := range users {
+		s.users[user] = struct{}{}
+	}
+}
+
+// CloseDay flushes counted usage, measures the previous UTC day's stored
+// records, active users and tracked shipments, and reports the day as
+// billing events. Closing a day again updates its usage but reports only
+// what was not reported before.
+func (s *UsageService) CloseDay(ctx context.Context) ([]types.BillingEvent, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	closed := day(s.now()).AddDate(0, 0, -1)
+	if err := s.repo.CloseDay(ctx, closed); err != nil {
+		return nil, err
+	}
+	events, err := s.repo.CreateBillingEvents(ctx, closed)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.publisher != nil {
+		for _, event := range events {
+			if err := s.publisher.Publish(ctx, EventBillingUsageReported, event); err != nil {
+				s.logger.Warn("Failed to publish billing event", "billing_event_id", event.ID, "error", err)
+			}
+		}
+	}
+	return events, nil
+}
+
+// GetUsage returns an organization's usage per metric between two days,
+// inclusive. The current day is partial until it is closed.
+func (s *UsageService) GetUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) (*types.UsageSummary, error) {
+	if err := s.authService.CheckPermission(ctx, "usage:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	today := day(s.now())
+	if to.IsZero() {
+		to = today
+	}
+	if from.IsZero() {
+		from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	from, to = day(from), day(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must not be before from", types.ErrInvalidUsage)
+	}
+	if to.Sub(from) >= MaxUsageDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: at most %d days can be queried", types.ErrInvalidUsage, MaxUsageDays)
+	}
+
+	daily, err := s.repo.ListUsage(ctx, orgID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	summary := SummarizeUsage(daily)
+	summary.OrganizationID = orgID
+	summary.From = from
+	summary.To = to
+	return summary, nil
+}
+
+// Totals returns an organization's usage of every metric between two days,
+// inclusive, for the modules reporting it to the organization's members
+func (s *UsageService) Totals(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]metering.Total, error) {
+	daily, err := s.repo.ListUsage(ctx, orgID, day(from), day(to))
+	if err != nil {
+		return nil, err
+	}
+	summary := SummarizeUsage(daily)
+	totals := make([]metering.Total, 0, len(summary.Metrics))
+	for _, usage := range summary.Metrics {
+		totals = append(totals, metering.Total{Metric: string(usage.Metric), Gauge: usage.Metric.IsGauge(), Total: usage.Total})
+	}
+	return totals, nil
+}
+
+// SummarizeUsage totals daily usage per metric, every metric included.
+// Counters are summed; gauges report their peak.
+func SummarizeUsage(daily []types.DailyUsage) *types.UsageSummary {
+	byMetric := make(map[types.Metric]*types.MetricUsage, len(types.Metrics))
+	summary := &types.UsageSummary{Metrics: make([]types.MetricUsage, 0, len(types.Metrics))}
+	for _, metric := range types.Metrics {
+		byMetric[metric] = &types.MetricUsage{Metric: metric, Daily: []types.DailyUsage{}}
+	}
+
+	for _, usage := range daily {
+		total, ok := byMetric[usage.Metric]
+		if !ok {
+			continue
+		}
+		total.Daily = append(total.Daily, usage)
+		if usage.Metric.IsGauge() {
+			if usage.Quantity > total.Total {
+				total.Total = usage.Quantity
+			}
+		} else {
+			total.Total += usage.Quantity
+		}
+	}
+
+	for _, metric := range types.Metrics {
+		summary.Metrics = append(summary.Metrics, *byMetric[metric])
+	}
+	return summary
+}
+
+// superAdmin checks the caller is a platform administrator
+func superAdmin(ctx context.Context) error {
+	authCtx, err := auth.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !authCtx.IsSuperAdmin {
+		return types.ErrForbidden
+	}
+	return nil
+}
+
+// ListBillingEvents returns billing events for the billing bridge to deliver,
+// oldest first
+func (s *UsageService) ListBillingEvents(ctx context.Context, filter types.BillingEventFilter) ([]types.BillingEvent, error) {
+	if err := superAdmin(ctx); err != nil {
+		return nil, err
+	}
+	switch filter.Status {
+	case "", types.BillingEventPending, types.BillingEventDelivered:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", types.ErrInvalidUsage, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultBillingEventLimit
+	}
+	if filter.Limit > MaxBillingEventLimit {
+		filter.Limit = MaxBillingEventLimit
+	}
+	return s.repo.ListBillingEvents(ctx, filter)
+}
+
+// MarkDelivered records that the billing provider accepted a billing event,
+// with the ID of its usage record there
+func (s *UsageService) MarkDelivered(ctx context.Context, id uuid.UUID, req types.DeliveredRequest) (*types.BillingEvent, error) {
+	if err := superAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.MarkDelivered(ctx, id, req.ExternalID)
+}
+
+// Mailer sends an email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// MeteredMailer counts the emails sent through a mailer against the
+// organization they are sent for
+type MeteredMailer struct {
+	mailer Mailer
+	usage  *UsageService
+}
+
+// WrapMailer returns a mailer counting the emails it sends
+func (s *UsageService) WrapMailer(mailer Mailer) *MeteredMailer {
+	return &MeteredMailer{mailer: mailer, usage: s}
+}
+
+// Send sends the email and counts it once it is sent. Emails sent outside an
+// organization's context are not counted.
+func (m *MeteredMailer) Send(ctx context.Context, to, subject, body string) error {
+	if err := m.mailer.Send(ctx, to, subject, body); err != nil {
+		return err
+	}
+	if orgID, ok := metering.OrganizationFrom(ctx); ok {
+		m.usage.Record(orgID, types.MetricEmailsSent, 1)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/metering/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsageRepo struct {
+	increments []types.UsageIncrement
+	users      []types.ActiveUser
+	closed     []time.Time
+	failAdd    error
+}
+
+func (f *fakeUsageRepo) AddUsage(ctx context.Context, increments []types.UsageIncrement) error {
+	if f.failAdd != nil {
+		return f.failAdd
+	}
+	f.increments = append(f.increments, increments...)
+	return nil
+}
+
+func (f *fakeUsageRepo) AddActiveUsers(ctx context.Context, users []types.ActiveUser) error {
+	f.users = append(f.users, users...)
+	return nil
+}
+
+func (f *fakeUsageRepo) CloseDay(ctx context.Context, day time.Time) error {
+	f.closed = append(f.closed, day)
+	return nil
+}
+
+func (f *fakeUsageRepo) CreateBillingEvents(ctx context.Context, day time.Time) ([]types.BillingEvent, error) {
+	return []types.BillingEvent{{ID: uuid.New(), Day: day, Metric: types.MetricAPICalls, Action: types.BillingActionIncrement}}, nil
+}
+
+func (f *fakeUsageRepo) ListUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.DailyUsage, error) {
+	return nil, nil
+}
+
+func (f *fakeUsageRepo) ListBillingEvents(ctx context.Context, filter types.BillingEventFilter) ([]types.BillingEvent, error) {
+	return nil, nil
+}
+
+func (f *fakeUsageRepo) MarkDelivered(ctx context.Context, id uuid.UUID, externalID string) (*types.BillingEvent, error) {
+	return nil, types.ErrBillingEventNotFound
+}
+
+type fakePublisher struct {
+	events []interface{}
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, eventType string, payload interface{}) error {
+	p.events = append(p.events, payload)
+	return nil
+}
+
+type fakeMailer struct {
+	err error
+}
+
+func (m fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	return m.err
+}
+
+func newTestUsageService(repo *fakeUsageRepo, publisher Publisher, now time.Time) *UsageService {
+	s := NewUsageService(repo, nil, publisher, nil)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestMiddlewareCountsAuthenticatedCalls(t *testing.T) {
+	repo := &fakeUsageRepo{}
+	now := time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC)
+	s := newTestUsageService(repo, nil, now)
+	orgID, userID := uuid.New(), uuid.New()
+
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/leads", nil)
+		req = req.WithContext(auth.WithAuthContext(req.Context(), &auth.AuthContext{UserID: userID, OrganizationID: orgID}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	// Unauthenticated and sandbox requests are not billed
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	sandbox := httptest.NewRequest(http.MethodGet, "/api/v1/leads", nil)
+	sandbox = sandbox.WithContext(auth.WithAuthContext(sandbox.Context(), &auth.AuthContext{UserID: userID, OrganizationID: uuid.New(), IsSandbox: true}))
+	handler.ServeHTTP(httptest.NewRecorder(), sandbox)
+
+	require.NoError(t, s.Flush(context.Background()))
+	require.Len(t, repo.increments, 1)
+	assert.Equal(t, types.UsageIncrement{
+		OrganizationID: orgID,
+		Day:            time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC),
+		Metric:         types.MetricAPICalls,
+		Quantity:       3,
+	}, repo.increments[0])
+	require.Len(t, repo.users, 1)
+	assert.Equal(t, userID, repo.users[0].UserID)
+
+	// A second flush has nothing left to add
+	require.NoError(t, s.Flush(context.Background()))
+	assert.Len(t, repo.increments, 1)
+}
+
+func TestFlushKeepsUsageThatFailedToSave(t *testing.T) {
+	repo := &fakeUsageRepo{failAdd: errors.New("connection refused")}
+	s := newTestUsageService(repo, nil, time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC))
+	orgID := uuid.New()
+
+	s.Record(orgID, types.MetricEmailsSent, 2)
+	require.Error(t, s.Flush(context.Background()))
+
+	repo.failAdd = nil
+	s.Record(orgID, types.MetricEmailsSent, 1)
+	require.NoError(t, s.Flush(context.Background()))
+	require.Len(t, repo.increments, 1)
+	assert.Equal(t, int64(3), repo.increments[0].Quantity)
+}
+
+func TestMeteredMailerCountsSentEmails(t *testing.T) {
+	repo := &fakeUsageRepo{}
+	s := newTestUsageService(repo, nil, time.Date(2025, 3, 4, 15, 0, 0, 0, time.UTC))
+	orgID := uuid.New()
+	ctx := metering.WithOrganization(context.Background(), orgID)
+
+	require.NoError(t, s.WrapMailer(fakeMailer{}).Send(ctx, "a@example.com", "Hi", "Body"))
+	require.Error(t, s.WrapMailer(fakeMailer{err: errors.New("rejected")}).Send(ctx, "b@example.com", "Hi", "Body"))
+	// No organization to bill
+	require.NoError(t, s.WrapMailer(fakeMailer{}).Send(context.Background(), "c@example.com", "Hi", "Body"))
+
+	require.NoError(t, s.Flush(context.Background()))
+	require.Len(t, repo.increments, 1)
+	assert.Equal(t, types.MetricEmailsSent, repo.increments[0].Metric)
+	assert.Equal(t, int64(1), repo.increments[0].Quantity)
+}
+
+func TestCloseDayReportsPreviousDay(t *testing.T) {
+	repo := &fakeUsageRepo{}
+	publisher := &fakePublisher{}
+	s := newTestUsageService(repo, publisher, time.Date(2025, 3, 4, 0, 0, 5, 0, time.UTC))
+
+	events, err := s.CloseDay(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, []time.Time{time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)}, repo.closed)
+	assert.Len(t, publisher.events, 1)
+	assert.Equal(t, time.Date(2025, 3, 3, 23, 59, 59, 0, time.UTC), events[0].Timestamp())
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	summary := SummarizeUsage([]types.DailyUsage{
+		{Day: day(1), Metric: types.MetricAPICalls, Quantity: 100},
+		{Day: day(2), Metric: types.MetricAPICalls, Quantity: 50},
+		{Day: day(1), Metric: types.MetricStoredRecords, Quantity: 900},
+		{Day: day(2), Metric: types.MetricStoredRecords, Quantity: 850},
+	})
+
+	require.Len(t, summary.Metrics, len(types.Metrics))
+	totals := map[types.Metric]int64{}
+	for _, metric := range summary.Metrics {
+		totals[metric.Metric] = metric.Total
+	}
+	assert.Equal(t, int64(150), totals[types.MetricAPICalls])
+	assert.Equal(t, int64(900), totals[types.MetricStoredRecords])
+	assert.Equal(t, int64(0), totals[types.MetricEmailsSent])
+}
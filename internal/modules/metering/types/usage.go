@@ -0,0 +1,135 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidUsage wraps validation failures of usage queries
+	ErrInvalidUsage = errors.New("invalid usage query")
+	// ErrBillingEventNotFound is returned when a billing event does not exist
+	ErrBillingEventNotFound = errors.New("billing event not found")
+	// ErrForbidden is returned when the caller is not a platform administrator
+	ErrForbidden = errors.New("billing events are only available to platform administrators")
+)
+
+// Metric is a metered usage dimension
+type Metric string
+
+const (
+	MetricAPICalls         Metric = "api_calls"
+	MetricStoredRecords    Metric = "stored_records"
+	MetricActiveUsers      Metric = "active_users"
+	MetricShipmentsTracked Metric = "shipments_tracked"
+	MetricEmailsSent       Metric = "emails_sent"
+)
+
+// Metrics lists every metric, in reporting order
+var Metrics = []Metric{MetricAPICalls, MetricStoredRecords, MetricActiveUsers, MetricShipmentsTracked, MetricEmailsSent}
+
+// IsGauge reports whether the metric is a level measured once a day rather
+// than a count added to through the day
+func (m Metric) IsGauge() bool {
+	return m == MetricStoredRecords || m == MetricActiveUsers
+}
+
+// BillingAction is how a billing event's quantity applies to the billing
+// period: counters are added, gauges replace the previous value
+type BillingAction string
+
+const (
+	BillingActionIncrement BillingAction = "increment"
+	BillingActionSet       BillingAction = "set"
+)
+
+// ActionFor returns the billing action of a metric
+func ActionFor(metric Metric) BillingAction {
+	if metric.IsGauge() {
+		return BillingActionSet
+	}
+	return BillingActionIncrement
+}
+
+// BillingEventStatus tracks delivery of a billing event to the billing provider
+type BillingEventStatus string
+
+const (
+	BillingEventPending   BillingEventStatus = "pending"
+	BillingEventDelivered BillingEventStatus = "delivered"
+)
+
+// UsageIncrement adds to a counter of an organization for a day
+type UsageIncrement struct {
+	OrganizationID uuid.UUID
+	Day            time.Time
+	Metric         Metric
+	Quantity       int64
+}
+
+// ActiveUser records that a user made API calls on a day
+type ActiveUser struct {
+	OrganizationID uuid.UUID
+	Day            time.Time
+	UserID         uuid.UUID
+}
+
+// DailyUsage is one metric of an organization for a day (UTC)
+type DailyUsage struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Day            time.Time `json:"day"`
+	Metric         Metric    `json:"metric"`
+	Quantity       int64     `json:"quantity"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// MetricUsage sums a metric over a period. Counters are totalled; for gauges
+// the total is the peak daily value.
+type MetricUsage struct {
+	Metric Metric       `json:"metric"`
+	Total  int64        `json:"total"`
+	Daily  []DailyUsage `json:"daily"`
+}
+
+// UsageSummary is an organization's usage over a period of days
+type UsageSummary struct {
+	OrganizationID uuid.UUID     `json:"organization_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Metrics        []MetricUsage `json:"metrics"`
+}
+
+// BillingEvent reports a closed day's usage of a metric, shaped for metered
+// billing usage records
+type BillingEvent struct {
+	ID             uuid.UUID          `json:"id"`
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	Metric         Metric             `json:"metric"`
+	Day            time.Time          `json:"day"`
+	Quantity       int64              `json:"quantity"`
+	Action         BillingAction      `json:"action"`
+	IdempotencyKey string             `json:"idempotency_key"`
+	Status         BillingEventStatus `json:"status"`
+	ExternalID     *string            `json:"external_id,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	DeliveredAt    *time.Time         `json:"delivered_at,omitempty"`
+}
+
+// Timestamp is the instant the usage is reported at: the last second of its day
+func (e BillingEvent) Timestamp() time.Time {
+	return e.Day.AddDate(0, 0, 1).Add(-time.Second)
+}
+
+// BillingEventFilter narrows the billing events listed for the billing bridge
+type BillingEventFilter struct {
+	OrganizationID *uuid.UUID
+	Status         BillingEventStatus
+	Limit          int
+}
+
+// DeliveredRequest marks a billing event as recorded by the billing provider
+type DeliveredRequest struct {
+	ExternalID string `json:"external_id,omitempty"`
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OffboardingHandler handles tenant exports, their download links and
+// tenant deletions
+type OffboardingHandler struct {
+	service *service.OffboardingService
+	logger  *slog.Logger
+}
+
+func NewOffboardingHandler(service *service.OffboardingService, logger *slog.Logger) *OffboardingHandler {
+	return &OffboardingHandler{service: service, logger: logger}
+}
+
+func (h *OffboardingHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/tenant-exports", h.ListExports)
+	router.POST("/api/v1/tenant-exports", h.RequestExport)
+	router.GET("/api/v1/tenant-exports/:id", h.GetExport)
+	router.POST("/api/v1/tenant-exports/:id/download-links", h.CreateDownloadLink)
+	router.POST("/api/v1/tenant-deletions", h.RequestDeletion)
+	router.GET("/api/v1/tenant-deletions/:id", h.GetDeletion)
+
+	// Public endpoints, authorized by the link token or open to anyone
+	// holding the certificate
+	router.GET(service.ExportDownloadPath+":token", h.Download)
+	router.GET("/public/v1/deletion-certificates/:id", h.VerifyCertificate)
+}
+
+// ListExports handles GET /api/v1/tenant-exports
+func (h *OffboardingHandler) ListExports(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	exports, err := h.service.ListExports(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, exports)
+}
+
+// RequestExport handles POST /api/v1/tenant-exports, queueing an export of
+// all the organization's data
+func (h *OffboardingHandler) RequestExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.service.RequestExport(r.Context(), authCtx.OrganizationID, authCtx.UserID)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, export)
+}
+
+// GetExport handles GET /api/v1/tenant-exports/:id
+func (h *OffboardingHandler) GetExport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.service.GetExport(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, export)
+}
+
+// CreateDownloadLink handles POST /api/v1/tenant-exports/:id/download-links
+func (h *OffboardingHandler) CreateDownloadLink(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional; an empty one takes the default lifetime
+	var req types.ExportLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.CreateDownloadLink(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// Download handles GET /public/v1/tenant-exports/download/:token, streaming
+// the export archive the link grants
+func (h *OffboardingHandler) Download(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	export, archive, err := h.service.OpenDownload(r.Context(), ps.ByName("token"))
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "export-"+export.ID.String()+".zip"))
+	w.Header().Set("Cache-Control", "no-store")
+	if export.SizeBytes != nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(*export.SizeBytes, 10))
+	}
+	if export.SHA256 != nil {
+		w.Header().Set("X-Content-SHA256", *export.SHA256)
+	}
+	if _, err := io.Copy(w, archive); err != nil {
+		h.logger.Error("Failed to stream tenant export", "export_id", export.ID, "error", err)
+	}
+}
+
+// RequestDeletion handles POST /api/v1/tenant-deletions, queueing the
+// deletion of all the organization's data
+func (h *OffboardingHandler) RequestDeletion(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.TenantDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.service.RequestDeletion(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, deletion)
+}
+
+// GetDeletion handles GET /api/v1/tenant-deletions/:id
+func (h *OffboardingHandler) GetDeletion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid deletion ID", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.service.GetDeletion(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deletion)
+}
+
+// VerifyCertificate handles GET /public/v1/deletion-certificates/:id
+func (h *OffboardingHandler) VerifyCertificate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid deletion ID", http.StatusBadRequest)
+		return
+	}
+
+	verification, err := h.service.VerifyCertificate(r.Context(), id)
+	if err != nil {
+		writeOffboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, verification)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeOffboardingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrLinkExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, service.ErrStorageUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/service"
+)
+
+// Runner builds queued exports, runs queued deletions and removes expired
+// archives in the background. Exports and deletions are claimed with row
+// locks, so every instance can run one.
+type Runner struct {
+	offboardingService *service.OffboardingService
+	interval           time.Duration
+	logger             *slog.Logger
+}
+
+func NewRunner(offboardingService *service.OffboardingService, interval time.Duration, logger *slog.Logger) *Runner {
+	return &Runner{
+		offboardingService: offboardingService,
+		interval:           interval,
+		logger:             logger,
+	}
+}
+
+// Start polls for queued work every interval until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.drain(ctx, "Tenant export processing failed", r.offboardingService.ProcessNextExport)
+				r.drain(ctx, "Tenant deletion processing failed", r.offboardingService.ProcessNextDeletion)
+				if _, err := r.offboardingService.ExpireArchives(ctx); err != nil {
+					r.logger.Error("Expiring tenant export archives failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// drain processes items until none are queued
+func (r *Runner) drain(ctx context.Context, failure string, next func(context.Context) (bool, error)) {
+	for ctx.Err() == nil {
+		processed, err := next(ctx)
+		if err != nil {
+			r.logger.Error(failure, "error", err)
+			return
+		}
+		if !processed {
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package offboarding
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OffboardingModule represents the tenant offboarding module
+type OffboardingModule struct {
+	offboardingService *service.OffboardingService
+	offboardingHandler *handler.OffboardingHandler
+	runner             *jobs.Runner
+	logger             *slog.Logger
+}
+
+// NewOffboardingModule creates a new offboarding module
+func NewOffboardingModule() *OffboardingModule {
+	return &OffboardingModule{}
+}
+
+// Name returns the module name
+func (m *OffboardingModule) Name() string {
+	return "offboarding"
+}
+
+// Init initializes the offboarding module and starts the background runner
+// building exports and running deletions
+func (m *OffboardingModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "offboarding")
+	m.logger.Info("Initializing offboarding module")
+
+	// Create repositories
+	offboardingRepo := repository.NewOffboardingRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.offboardingService = service.NewOffboardingService(offboardingRepo, authAdapter, m.logger)
+
+	// Create background runner
+	m.runner = jobs.NewRunner(m.offboardingService, service.PollInterval, m.logger)
+	m.runner.Start(ctx)
+
+	// Create handlers
+	m.offboardingHandler = handler.NewOffboardingHandler(m.offboardingService, m.logger)
+
+	m.logger.Info("Offboarding module initialized successfully")
+	return nil
+}
+
+// SetStorage sets the file storage holding attachment files and export
+// archives. Exports and deletions fail until it is set. It must be called
+// after Init.
+func (m *OffboardingModule) SetStorage(files service.FileStore) {
+	if m.offboardingService != nil {
+		m.offboardingService.SetStorage(files)
+	}
+}
+
+// SetSigningKey signs deletion certificates. It must be called after Init.
+func (m *OffboardingModule) SetSigningKey(key []byte) {
+	if m.offboardingService != nil {
+		m.offboardingService.SetSigningKey(key)
+	}
+}
+
+// RegisterRoutes registers offboarding module routes
+func (m *OffboardingModule) RegisterRoutes(router interface{}) {
+	if m.offboardingHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.offboardingHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the offboarding module
+func (m *OffboardingModule) RegisterEventHandlers(bus interface{}) {
+	// Exports and deletions are requested through the API; no events needed
+}
+
+// Health checks the health of the offboarding module
+func (m *OffboardingModule) Health() error {
+	return nil
+}
@@ -0,0 +1,763 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when an export, link or deletion does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyQueued is returned when the organization already has an
+	// export queued or running, or a deletion that has not failed
+	ErrAlreadyQueued = errors.New("already queued")
+)
+
+// OffboardingRepo defines the interface for offboarding repository operations
+type OffboardingRepo interface {
+	FindOrganization(ctx context.Context, orgID uuid.UUID) (*types.OrganizationInfo, error)
+	CountActiveLegalHolds(ctx context.Context, orgID uuid.UUID) (int, error)
+
+	CreateExport(ctx context.Context, export types.TenantExport) error
+	FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.TenantExport, error)
+	ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.TenantExport, error)
+	ClaimExport(ctx context.Context, staleBefore time.Time) (*types.TenantExport, error)
+	UpdateExport(ctx context.Context, export types.TenantExport) error
+	ListExpiredExports(ctx context.Context, now time.Time) ([]types.TenantExport, error)
+	WriteTables(ctx context.Context, orgID uuid.UUID, open func(table *types.ExportTable) (io.Writer, error)) ([]types.ExportTable, []types.ExportAttachment, error)
+
+	CreateLink(ctx context.Context, link types.ExportLink) error
+	FindLinkByTokenHash(ctx context.Context, tokenHash string) (*types.ExportLink, error)
+	RecordDownload(ctx context.Context, link types.ExportLink, at time.Time) error
+
+	CreateDeletion(ctx context.Context, deletion types.TenantDeletion) error
+	FindDeletion(ctx context.Context, id uuid.UUID) (*types.TenantDeletion, error)
+	ClaimDeletion(ctx context.Context, staleBefore time.Time) (*types.TenantDeletion, error)
+	UpdateDeletion(ctx context.Context, deletion types.TenantDeletion) error
+	StorageKeys(ctx context.Context, orgID uuid.UUID) ([]string, error)
+	DeleteOrganizationData(ctx context.Context, orgID uuid.UUID, deletedAt time.Time) ([]types.TableDeletion, error)
+	CountRemainingRows(ctx context.Context, orgID uuid.UUID) (map[string]int64, error)
+}
+
+// OffboardingRepository exports and deletes all data of an organization
+type OffboardingRepository struct {
+	db *sql.DB
+}
+
+// Ensure OffboardingRepository implements OffboardingRepo interface
+var _ OffboardingRepo = &OffboardingRepository{}
+
+func NewOffboardingRepository(db *sql.DB) *OffboardingRepository {
+	return &OffboardingRepository{db: db}
+}
+
+// offboardingTables record the offboarding itself. They are neither exported
+// nor deleted, so the certificate outlives the data it covers.
+var offboardingTables = []string{
+	"tenant_exports",
+	"tenant_export_links",
+	"tenant_deletions",
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *OffboardingRepository) FindOrganization(ctx context.Context, orgID uuid.UUID) (*types.OrganizationInfo, error) {
+	var org types.OrganizationInfo
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, name, slug, deleted_at FROM organizations WHERE id = $1`,
+		orgID,
+	).Scan(&org.ID, &org.Name, &org.Slug, &org.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("organization %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+	return &org, nil
+}
+
+func (r *OffboardingRepository) CountActiveLegalHolds(ctx context.Context, orgID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM retention_legal_holds WHERE organization_id = $1 AND released_at IS NULL`,
+		orgID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count legal holds: %w", err)
+	}
+	return count, nil
+}
+
+const exportColumns = `id, organization_id, status, storage_key, size_bytes, sha256, manifest, error,
+	requested_by, created_at, started_at, completed_at, expires_at, first_downloaded_at`
+
+func scanExport(row rowScanner) (*types.TenantExport, error) {
+	var e types.TenantExport
+	var manifest []byte
+	err := row.Scan(&e.ID, &e.OrganizationID, &e.Status, &e.StorageKey, &e.SizeBytes, &e.SHA256, &manifest, &e.Error,
+		&e.RequestedBy, &e.CreatedAt, &e.StartedAt, &e.CompletedAt, &e.ExpiresAt, &e.FirstDownloadedAt)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		e.Manifest = &types.ExportManifest{}
+		if err := json.Unmarshal(manifest, e.Manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode export manifest: %w", err)
+		}
+	}
+	return &e, nil
+}
+
+func (r *OffboardingRepository) CreateExport(ctx context.Context, export types.TenantExport) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tenant_exports (id, organization_id, status, requested_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`,
+		export.ID, export.OrganizationID, export.Status, export.RequestedBy, export.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("tenant export %w", ErrAlreadyQueued)
+		}
+		return fmt.Errorf("failed to create tenant export: %w", err)
+	}
+	return nil
+}
+
+func (r *OffboardingRepository) FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.TenantExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM tenant_exports WHERE id = $1 AND organization_id = $2`
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("tenant export %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find tenant export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *OffboardingRepository) ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.TenantExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM tenant_exports
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	return r.queryExports(ctx, query, orgID, limit)
+}
+
+// ClaimExport marks the oldest pending export as running and returns it.
+// Exports left running since staleBefore, e.g. by a crashed instance, are
+// claimed again and start over. It returns nil when there is nothing to do.
+func (r *OffboardingRepository) ClaimExport(ctx context.Context, staleBefore time.Time) (*types.TenantExport, error) {
+	query := `
+		UPDATE tenant_exports
+		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM tenant_exports
+			WHERE status = 'pending' OR (status = 'running' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, staleBefore))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim tenant export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *OffboardingRepository) UpdateExport(ctx context.Context, export types.TenantExport) error {
+	var manifest []byte
+	if export.Manifest != nil {
+		var err error
+		if manifest, err = json.Marshal(export.Manifest); err != nil {
+			return fmt.Errorf("failed to encode export manifest: %w", err)
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tenant_exports
+		SET status = $2, storage_key = $3, size_bytes = $4, sha256 = $5, manifest = $6, error = $7,
+			completed_at = $8, expires_at = $9, updated_at = NOW()
+		WHERE id = $1`,
+		export.ID, export.Status, export.StorageKey, export.SizeBytes, export.SHA256, manifest, export.Error,
+		export.CompletedAt, export.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant export: %w", err)
+	}
+	return nil
+}
+
+// ListExpiredExports returns the completed exports whose archive expired
+func (r *OffboardingRepository) ListExpiredExports(ctx context.Context, now time.Time) ([]types.TenantExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM tenant_exports
+		WHERE status = 'completed' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+	return r.queryExports(ctx, query, now, 100)
+}
+
+func (r *OffboardingRepository) queryExports(ctx context.Context, query string, args ...interface{}) ([]types.TenantExport, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []types.TenantExport{}
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tenant export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tenant exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// WriteTables writes every row of the organization, from one snapshot, as
+// JSON lines to the writer open returns for its table. The organizations row
+// itself is included. It returns the tables written and the organization's
+// attachment files as of the same snapshot.
+func (r *OffboardingRepository) WriteTables(ctx context.Context, orgID uuid.UUID, open func(table *types.ExportTable) (io.Writer, error)) ([]types.ExportTable, []types.ExportAttachment, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin export: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables, err := exportTables(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range tables {
+		w, err := open(&tables[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if tables[i].Rows, err = writeTableRows(ctx, tx, tables[i].Table, orgID, w); err != nil {
+			return nil, nil, fmt.Errorf("failed to export %s: %w", tables[i].Table, err)
+		}
+	}
+
+	attachments, err := listAttachments(ctx, tx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tables, attachments, nil
+}
+
+// exportTables lists the organizations table and every table of the current
+// schema carrying an organization_id column, with their columns in order
+func exportTables(ctx context.Context, tx *sql.Tx) ([]types.ExportTable, error) {
+	query := `
+		SELECT c.table_name, c.column_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema()
+			AND t.table_type = 'BASE TABLE'
+			AND c.table_name <> ALL($1)
+			AND (c.table_name = 'organizations' OR EXISTS (
+				SELECT 1 FROM information_schema.columns o
+				WHERE o.table_schema = c.table_schema
+					AND o.table_name = c.table_name
+					AND o.column_name = 'organization_id'
+			))
+		ORDER BY c.table_name, c.ordinal_position
+	`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(offboardingTables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []types.ExportTable
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan table column: %w", err)
+		}
+		if n := len(tables); n == 0 || tables[n-1].Table != table {
+			tables = append(tables, types.ExportTable{Table: table})
+		}
+		last := &tables[len(tables)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during table iteration: %w", err)
+	}
+
+	return tables, nil
+}
+
+// writeTableRows writes the organization's rows of one table as JSON lines
+func writeTableRows(ctx context.Context, tx *sql.Tx, table string, orgID uuid.UUID, w io.Writer) (int64, error) {
+	column := "organization_id"
+	if table == "organizations" {
+		column = "id"
+	}
+	query := fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t WHERE t.%s = $1", pq.QuoteIdentifier(table), column)
+
+	rows, err := tx.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	out := bufio.NewWriter(w)
+	var count int64
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return count, err
+		}
+		if _, err := out.WriteString(line); err != nil {
+			return count, err
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	return count, out.Flush()
+}
+
+func listAttachments(ctx context.Context, tx *sql.Tx, orgID uuid.UUID) ([]types.ExportAttachment, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, file_name, storage_key FROM attachments WHERE organization_id = $1 ORDER BY id`,
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []types.ExportAttachment{}
+	for rows.Next() {
+		var a types.ExportAttachment
+		if err := rows.Scan(&a.ID, &a.FileName, &a.StorageKey); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+const linkColumns = `id, organization_id, export_id, token_hash, expires_at, created_by, created_at, download_count, last_downloaded_at`
+
+func (r *OffboardingRepository) CreateLink(ctx context.Context, link types.ExportLink) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tenant_export_links (id, organization_id, export_id, token_hash, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		link.ID, link.OrganizationID, link.ExportID, link.TokenHash, link.ExpiresAt, link.CreatedBy, link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create export link: %w", err)
+	}
+	return nil
+}
+
+func (r *OffboardingRepository) FindLinkByTokenHash(ctx context.Context, tokenHash string) (*types.ExportLink, error) {
+	var l types.ExportLink
+	err := r.db.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM tenant_export_links WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&l.ID, &l.OrganizationID, &l.ExportID, &l.TokenHash, &l.ExpiresAt, &l.CreatedBy, &l.CreatedAt,
+		&l.DownloadCount, &l.LastDownloaded)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("export link %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find export link: %w", err)
+	}
+	return &l, nil
+}
+
+// RecordDownload counts a download through the link and marks the export as
+// downloaded the first time
+func (r *OffboardingRepository) RecordDownload(ctx context.Context, link types.ExportLink, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tenant_export_links
+		SET download_count = download_count + 1, last_downloaded_at = $2
+		WHERE id = $1`,
+		link.ID, at,
+	); err != nil {
+		return fmt.Errorf("failed to record export download: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tenant_exports
+		SET first_downloaded_at = COALESCE(first_downloaded_at, $2)
+		WHERE id = $1`,
+		link.ExportID, at,
+	); err != nil {
+		return fmt.Errorf("failed to record export download: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit export download: %w", err)
+	}
+	return nil
+}
+
+const deletionColumns = `id, organization_id, export_id, status, requested_by, created_at, started_at, completed_at, certificate, error`
+
+func scanDeletion(row rowScanner) (*types.TenantDeletion, error) {
+	var d types.TenantDeletion
+	var certificate []byte
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.ExportID, &d.Status, &d.RequestedBy, &d.CreatedAt,
+		&d.StartedAt, &d.CompletedAt, &certificate, &d.Error)
+	if err != nil {
+		return nil, err
+	}
+	if certificate != nil {
+		d.Certificate = &types.DeletionCertificate{}
+		if err := json.Unmarshal(certificate, d.Certificate); err != nil {
+			return nil, fmt.Errorf("failed to decode deletion certificate: %w", err)
+		}
+	}
+	return &d, nil
+}
+
+func (r *OffboardingRepository) CreateDeletion(ctx context.Context, deletion types.TenantDeletion) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tenant_deletions (id, organization_id, export_id, status, requested_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		deletion.ID, deletion.OrganizationID, deletion.ExportID, deletion.Status, deletion.RequestedBy, deletion.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("tenant deletion %w", ErrAlreadyQueued)
+		}
+		return fmt.Errorf("failed to create tenant deletion: %w", err)
+	}
+	return nil
+}
+
+// FindDeletion finds a deletion by its ID alone, as the organization it
+// belongs to may no longer have any members to ask for it
+func (r *OffboardingRepository) FindDeletion(ctx context.Context, id uuid.UUID) (*types.TenantDeletion, error) {
+	query := `SELECT ` + deletionColumns + ` FROM tenant_deletions WHERE id = $1`
+
+	deletion, err := scanDeletion(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("tenant deletion %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find tenant deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+// ClaimDeletion marks the oldest pending deletion as running and returns it.
+// A deletion runs in one transaction, so one left running since staleBefore
+// deleted nothing and is claimed again. It returns nil when there is nothing
+// to do.
+func (r *OffboardingRepository) ClaimDeletion(ctx context.Context, staleBefore time.Time) (*types.TenantDeletion, error) {
+	query := `
+		UPDATE tenant_deletions
+		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM tenant_deletions
+			WHERE status = 'pending' OR (status = 'running' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deletionColumns
+
+	deletion, err := scanDeletion(r.db.QueryRowContext(ctx, query, staleBefore))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim tenant deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+func (r *OffboardingRepository) UpdateDeletion(ctx context.Context, deletion types.TenantDeletion) error {
+	var certificate []byte
+	if deletion.Certificate != nil {
+		var err error
+		if certificate, err = json.Marshal(deletion.Certificate); err != nil {
+			return fmt.Errorf("failed to encode deletion certificate: %w", err)
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tenant_deletions
+		SET status = $2, completed_at = $3, certificate = $4, error = $5, updated_at = NOW()
+		WHERE id = $1`,
+		deletion.ID, deletion.Status, deletion.CompletedAt, certificate, deletion.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant deletion: %w", err)
+	}
+	return nil
+}
+
+// StorageKeys lists the files a deletion removes from storage: the
+// organization's attachment files no other organization refers to, and its
+// export archives
+func (r *OffboardingRepository) StorageKeys(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT a.storage_key
+		FROM attachments a
+		WHERE a.organization_id = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM attachments o
+				WHERE o.storage_key = a.storage_key AND o.organization_id <> a.organization_id
+			)
+		UNION
+		SELECT storage_key FROM tenant_exports
+		WHERE organization_id = $1 AND storage_key IS NOT NULL AND status = 'completed'
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization files: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan storage key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating storage keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteOrganizationData deletes every organization-scoped row except the
+// offboarding records, revokes the export links and marks the organization
+// deleted, in one transaction. Tables are discovered from the schema so new
+// modules are covered automatically. Deletes that hit a foreign key are
+// retried after the tables referencing them have been cleared.
+func (r *OffboardingRepository) DeleteOrganizationData(ctx context.Context, orgID uuid.UUID, deletedAt time.Time) ([]types.TableDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the organization row so no member can be added while it is emptied
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT true FROM organizations WHERE id = $1 FOR UPDATE`,
+		orgID,
+	).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("organization %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+
+	tables, err := organizationTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	deletions := make([]types.TableDeletion, 0, len(tables))
+	pending := tables
+	for len(pending) > 0 {
+		var blocked []string
+		var lastErr error
+
+		for _, table := range pending {
+			deleted, err := deleteOrgRows(ctx, tx, table, orgID)
+			if err != nil {
+				var pgErr *pgconn.PgError
+				if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+					// foreign_key_violation: another table still references these rows
+					blocked = append(blocked, table)
+					lastErr = err
+					continue
+				}
+				return nil, fmt.Errorf("failed to delete %s: %w", table, err)
+			}
+			deletions = append(deletions, types.TableDeletion{Table: table, RowsDeleted: deleted})
+		}
+
+		if len(blocked) == len(pending) {
+			return nil, fmt.Errorf("failed to delete organization data, %d tables still referenced: %w", len(blocked), lastErr)
+		}
+		pending = blocked
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tenant_export_links WHERE organization_id = $1`, orgID); err != nil {
+		return nil, fmt.Errorf("failed to revoke export links: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tenant_exports SET status = 'expired', updated_at = $2
+		WHERE organization_id = $1 AND status = 'completed'`,
+		orgID, deletedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to expire tenant exports: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE organizations SET deleted_at = COALESCE(deleted_at, $2), updated_at = NOW() WHERE id = $1`,
+		orgID, deletedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to mark organization deleted: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organization deletion: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// CountRemainingRows counts the organization's rows left in each table, to
+// verify a deletion. Tables without rows are left out.
+func (r *OffboardingRepository) CountRemainingRows(ctx context.Context, orgID uuid.UUID) (map[string]int64, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables, err := organizationTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[string]int64)
+	for _, table := range tables {
+		var count int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE organization_id = $1", pq.QuoteIdentifier(table))
+		if err := tx.QueryRowContext(ctx, query, orgID).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		if count > 0 {
+			remaining[table] = count
+		}
+	}
+
+	return remaining, nil
+}
+
+// organizationTables lists the tables in the current schema that carry an
+// organization_id column, excluding the offboarding tables
+func organizationTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	query := `
+		SELECT c.table_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema()
+			AND c.column_name = 'organization_id'
+			AND t.table_type = 'BASE TABLE'
+			AND c.table_name <> ALL($1)
+		ORDER BY c.table_name
+	`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(offboardingTables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during table iteration: %w", err)
+	}
+
+	return tables, nil
+}
+
+// deleteOrgRows deletes the organization's rows from one table inside a
+// savepoint so a foreign key failure does not abort the whole transaction
+func deleteOrgRows(ctx context.Context, tx *sql.Tx, table string, orgID uuid.UUID) (int64, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT tenant_deletion"); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE organization_id = $1", pq.QuoteIdentifier(table)),
+		orgID,
+	)
+	if err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT tenant_deletion"); rbErr != nil {
+			return 0, rbErr
+		}
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT tenant_deletion"); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
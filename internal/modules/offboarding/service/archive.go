@@ -0,0 +1,262 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+)
+
+// ArchiveFormat identifies the layout of export archives in their manifest
+const ArchiveFormat = "alieze-tenant-export/v1"
+
+// moduleTables groups tables into the module directories of an archive by
+// table name prefix. The first matching prefix wins; tables matching none
+// are grouped under "other".
+var moduleTables = []struct {
+	module   string
+	prefixes []string
+}{
+	{"organization", []string{"organization", "companies", "sequences", "permission_", "user_role_assignments", "role_permission_groups", "retention_", "security_events", "audit_exports", "compliance_"}},
+	{"crm", []string{"lead", "contact", "activities", "lost_reasons", "sales_teams", "utm_", "territories", "assignment_", "duplicate_"}},
+	{"sales", []string{"sales_", "pos_", "pricelists", "intercompany_"}},
+	{"accounting", []string{"account_", "analytic_accounts", "invoice", "payment", "bank_accounts", "fiscal_positions", "budget", "collection_", "dunning_", "commission_", "fixed_assets", "asset_"}},
+	{"inventory", []string{"stock_", "inventory_", "warehouses", "product", "uom_", "replenishment_", "quality_", "barcode_", "mobile_scanning_", "bom_", "manufacturing_", "work_orders", "workcenters", "procurement_"}},
+	{"delivery", []string{"delivery_"}},
+	{"hr", []string{"employee", "departments", "job_positions", "job_postings", "leave_", "candidate", "interviews", "recruitment_", "onboarding_", "review", "goal", "certification_", "timesheets", "equipment_", "calibration_"}},
+	{"documents", []string{"attachment", "document_", "generated_documents", "knowledge_"}},
+	{"projects", []string{"projects", "tasks", "task_stages", "resources"}},
+}
+
+// archiveModule returns the module directory a table is exported to
+func archiveModule(table string) string {
+	for _, group := range moduleTables {
+		for _, prefix := range group.prefixes {
+			if strings.HasPrefix(table, prefix) {
+				return group.module
+			}
+		}
+	}
+	return "other"
+}
+
+// archiveFileName makes an attachment's file name safe to use as the last
+// element of an archive path
+func archiveFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// archiveWriter writes the entries of a zip archive and records the size
+// and SHA-256 of each for the manifest
+type archiveWriter struct {
+	zw       *zip.Writer
+	modified time.Time
+	files    []types.ExportManifestFile
+
+	path string
+	hash hash.Hash
+	size int64
+}
+
+func newArchiveWriter(w io.Writer, modified time.Time) *archiveWriter {
+	return &archiveWriter{zw: zip.NewWriter(w), modified: modified}
+}
+
+// create starts a new entry. The previous entry is complete once the next
+// one is created.
+func (a *archiveWriter) create(path string) (io.Writer, error) {
+	a.finish()
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Deflate, Modified: a.modified})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s to archive: %w", path, err)
+	}
+	a.path, a.hash, a.size = path, sha256.New(), 0
+	return io.MultiWriter(w, a.hash, (*countingWriter)(&a.size)), nil
+}
+
+func (a *archiveWriter) finish() {
+	if a.path == "" {
+		return
+	}
+	a.files = append(a.files, types.ExportManifestFile{
+		Path:      a.path,
+		SizeBytes: a.size,
+		SHA256:    hex.EncodeToString(a.hash.Sum(nil)),
+	})
+	a.path = ""
+}
+
+// close writes the manifest, listing every other entry, and completes the archive
+func (a *archiveWriter) close(manifest *types.ExportManifest) error {
+	a.finish()
+	manifest.Files = a.files
+
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: "manifest.json", Method: zip.Deflate, Modified: a.modified})
+	if err != nil {
+		return fmt.Errorf("failed to add manifest to archive: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return a.zw.Close()
+}
+
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// writeArchive writes the organization's data and attachment files to w as
+// a zip archive with a README and a manifest
+func (s *OffboardingService) writeArchive(ctx context.Context, export types.TenantExport, w io.Writer) (*types.ExportManifest, error) {
+	generatedAt := s.now().UTC()
+	archive := newArchiveWriter(w, generatedAt)
+
+	tables, attachments, err := s.repo.WriteTables(ctx, export.OrganizationID, func(table *types.ExportTable) (io.Writer, error) {
+		table.Module = archiveModule(table.Table)
+		table.Path = fmt.Sprintf("data/%s/%s.jsonl", table.Module, table.Table)
+		return archive.create(table.Path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a := &attachments[i]
+		file, err := s.files.Download(ctx, a.StorageKey)
+		if err != nil {
+			// A lost file must not keep the customer from the rest of their data
+			a.Error = err.Error()
+			continue
+		}
+		path := fmt.Sprintf("attachments/%s/%s", a.ID, archiveFileName(a.FileName))
+		entry, err := archive.create(path)
+		if err != nil {
+			file.Reader.Close()
+			return nil, err
+		}
+		a.SizeBytes, err = io.Copy(entry, file.Reader)
+		file.Reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy attachment %s: %w", a.ID, err)
+		}
+		a.Path = path
+	}
+
+	manifest := &types.ExportManifest{
+		Format:         ArchiveFormat,
+		ExportID:       export.ID,
+		OrganizationID: export.OrganizationID,
+		GeneratedAt:    generatedAt,
+		Tables:         tables,
+		Attachments:    attachments,
+	}
+
+	readme, err := archive.create("README.md")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(readme, archiveReadme(manifest)); err != nil {
+		return nil, fmt.Errorf("failed to write README: %w", err)
+	}
+
+	if err := archive.close(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// archiveReadme documents the layout and format of an archive, listing its
+// tables by module
+func archiveReadme(manifest *types.ExportManifest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Organization data export\n\n")
+	fmt.Fprintf(&b, "Organization: %s\n", manifest.OrganizationID)
+	fmt.Fprintf(&b, "Export: %s\n", manifest.ExportID)
+	fmt.Fprintf(&b, "Generated: %s\n", manifest.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Format: %s\n\n", manifest.Format)
+
+	b.WriteString(`## Layout
+
+- data/<module>/<table>.jsonl: the organization's records, one table per file
+- attachments/<attachment id>/<file name>: the files attached to records; the
+  attachments table in data/documents/attachments.jsonl describes each file
+- manifest.json: the tables with their columns and row counts, the
+  attachments, and the size and SHA-256 of every other file of the archive
+
+## Records
+
+Each line of a .jsonl file is one record, a JSON object with one key per
+column. Identifiers are UUID strings, and records refer to each other by
+them just as in the application. Timestamps are ISO 8601 with a UTC offset,
+decimal amounts are JSON numbers, and JSON columns are embedded as is.
+Empty columns are null.
+
+All records of the organization were read from a single consistent snapshot.
+Shared reference data, such as currencies and countries, and user sign-in
+accounts are not part of an organization and are not included.
+
+## Verifying the archive
+
+The SHA-256 of every file is listed in manifest.json under "files", e.g.
+"sha256sum data/crm/leads.jsonl". The SHA-256 of the archive itself is shown
+with the export in the application.
+
+## Tables
+`)
+
+	byModule := make(map[string][]types.ExportTable)
+	var modules []string
+	for _, table := range manifest.Tables {
+		if _, ok := byModule[table.Module]; !ok {
+			modules = append(modules, table.Module)
+		}
+		byModule[table.Module] = append(byModule[table.Module], table)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		fmt.Fprintf(&b, "\n### %s\n\n", module)
+		for _, table := range byModule[module] {
+			fmt.Fprintf(&b, "- %s: %d records (%s)\n", table.Table, table.Rows, strings.Join(table.Columns, ", "))
+		}
+	}
+
+	var missing int
+	for _, a := range manifest.Attachments {
+		if a.Path == "" {
+			missing++
+		}
+	}
+	fmt.Fprintf(&b, "\n## Attachments\n\n%d files", len(manifest.Attachments)-missing)
+	if missing > 0 {
+		fmt.Fprintf(&b, "; %d could not be read from storage and are listed with an error in manifest.json", missing)
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
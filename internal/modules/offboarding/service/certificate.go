@@ -0,0 +1,57 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+)
+
+// certificateDigest returns the SHA-256 of the certificate's JSON without
+// its digest and signature
+func certificateDigest(cert types.DeletionCertificate) (string, error) {
+	cert.Digest, cert.Signature = "", ""
+	data, err := json.Marshal(cert)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode deletion certificate: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func certificateSignature(digest string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(digest))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signCertificate sets the certificate's digest and, with a key, its signature
+func signCertificate(cert *types.DeletionCertificate, key []byte) error {
+	digest, err := certificateDigest(*cert)
+	if err != nil {
+		return err
+	}
+	cert.Digest = digest
+	cert.Signature = ""
+	if len(key) > 0 {
+		cert.Signature = certificateSignature(digest, key)
+	}
+	return nil
+}
+
+// verifyCertificate checks that the certificate is unchanged since it was
+// signed. Without a key only the digest can be checked, so signed is false.
+func verifyCertificate(cert types.DeletionCertificate, key []byte) (valid bool, signed bool) {
+	digest, err := certificateDigest(cert)
+	if err != nil || !hmac.Equal([]byte(digest), []byte(cert.Digest)) {
+		return false, false
+	}
+	if len(key) == 0 {
+		return true, false
+	}
+	expected := certificateSignature(digest, key)
+	return hmac.Equal([]byte(expected), []byte(cert.Signature)), true
+}
@@ -0,0 +1,592 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ArchiveRetention is how long an export archive stays downloadable
+	ArchiveRetention = 7 * 24 * time.Hour
+	// DefaultLinkLifetime is how long a download link is valid by default
+	DefaultLinkLifetime = 24 * time.Hour
+	// MaxLinkLifetime bounds how long a download link can be valid
+	MaxLinkLifetime = 7 * 24 * time.Hour
+	// PollInterval is how often queued exports and deletions are picked up
+	PollInterval = 30 * time.Second
+	// ExportStaleAfter is how long an export may run before it is started over
+	ExportStaleAfter = 2 * time.Hour
+	// DeletionStaleAfter is how long a deletion may run before it is started over
+	DeletionStaleAfter = 2 * time.Hour
+	// ExportDownloadPath is where download links are served, followed by the token
+	ExportDownloadPath = "/public/v1/tenant-exports/download/"
+	// recentExports is the number of exports listed
+	recentExports = 50
+)
+
+var (
+	// ErrInvalid wraps validation failures of offboarding requests
+	ErrInvalid = errors.New("invalid request")
+	// ErrLinkExpired is returned for a download link past its expiry, or for
+	// an archive that is no longer kept
+	ErrLinkExpired = errors.New("download link has expired")
+	// ErrStorageUnavailable is returned when no file storage is configured
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+)
+
+// AuthService defines the permission check used by the offboarding service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// FileStore is the file storage holding attachment files and export archives
+type FileStore interface {
+	Upload(ctx context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error)
+	Download(ctx context.Context, key string) (*storage.File, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// OffboardingService exports everything an organization holds when it
+// leaves, then deletes it and certifies the deletion
+type OffboardingService struct {
+	repo        repository.OffboardingRepo
+	authService AuthService
+	files       FileStore
+	signingKey  []byte
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewOffboardingService(repo repository.OffboardingRepo, authService AuthService, logger *slog.Logger) *OffboardingService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &OffboardingService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetStorage sets the file storage archives are written to and attachment
+// files are read from and deleted from
+func (s *OffboardingService) SetStorage(files FileStore) {
+	s.files = files
+}
+
+// SetSigningKey signs deletion certificates with an HMAC of their digest
+func (s *OffboardingService) SetSigningKey(key []byte) {
+	s.signingKey = key
+}
+
+// RequestExport queues a full export of the organization. While an export
+// is queued or running, that export is returned instead.
+func (s *OffboardingService) RequestExport(ctx context.Context, orgID, userID uuid.UUID) (*types.TenantExport, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:export"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.files == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	export := types.TenantExport{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Status:         types.ExportStatusPending,
+		RequestedBy:    userID,
+		CreatedAt:      s.now(),
+	}
+	if err := s.repo.CreateExport(ctx, export); err != nil {
+		if !errors.Is(err, repository.ErrAlreadyQueued) {
+			return nil, err
+		}
+		exports, err := s.repo.ListExports(ctx, orgID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(exports) == 0 {
+			return nil, fmt.Errorf("tenant export %w", repository.ErrNotFound)
+		}
+		return &exports[0], nil
+	}
+
+	s.logger.Info("tenant export requested", "organization_id", orgID, "export_id", export.ID)
+	return &export, nil
+}
+
+// ListExports returns the organization's most recent exports
+func (s *OffboardingService) ListExports(ctx context.Context, orgID uuid.UUID) ([]types.TenantExport, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:export"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListExports(ctx, orgID, recentExports)
+}
+
+// GetExport returns an export with its manifest
+func (s *OffboardingService) GetExport(ctx context.Context, orgID, id uuid.UUID) (*types.TenantExport, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:export"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindExport(ctx, orgID, id)
+}
+
+// ProcessNextExport builds the archive of the oldest queued export and
+// uploads it to storage. It reports whether an export was processed.
+func (s *OffboardingService) ProcessNextExport(ctx context.Context) (bool, error) {
+	export, err := s.repo.ClaimExport(ctx, s.now().Add(-ExportStaleAfter))
+	if err != nil || export == nil {
+		return false, err
+	}
+	logger := s.logger.With("export_id", export.ID, "organization_id", export.OrganizationID)
+
+	fail := func(reason error) (bool, error) {
+		logger.Error("tenant export failed", "error", reason)
+		now := s.now()
+		message := reason.Error()
+		export.Status = types.ExportStatusFailed
+		export.Error = &message
+		export.CompletedAt = &now
+		return true, s.repo.UpdateExport(ctx, *export)
+	}
+
+	if s.files == nil {
+		return fail(ErrStorageUnavailable)
+	}
+
+	// The archive is spooled to disk so its checksum is known before upload
+	tmp, err := os.CreateTemp("", "tenant-export-*.zip")
+	if err != nil {
+		return fail(fmt.Errorf("failed to create archive file: %w", err))
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	manifest, err := s.writeArchive(ctx, *export, tmp)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Leave the export running; it is started over once it goes stale
+			return true, ctx.Err()
+		}
+		return fail(err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fail(fmt.Errorf("failed to read archive: %w", err))
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, tmp)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read archive: %w", err))
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fail(fmt.Errorf("failed to read archive: %w", err))
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	key := fmt.Sprintf("tenant-exports/%s/%s.zip", export.OrganizationID, export.ID)
+	if _, err := s.files.Upload(ctx, storage.UploadOptions{
+		Key:         key,
+		Reader:      tmp,
+		ContentType: "application/zip",
+		Size:        size,
+		Metadata:    map[string]string{"sha256": sum},
+		ACL:         "private",
+	}); err != nil {
+		return fail(fmt.Errorf("failed to upload archive: %w", err))
+	}
+
+	now := s.now()
+	expiresAt := now.Add(ArchiveRetention)
+	export.Status = types.ExportStatusCompleted
+	export.StorageKey = &key
+	export.SizeBytes = &size
+	export.SHA256 = &sum
+	export.Manifest = manifest
+	export.Error = nil
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	logger.Info("tenant export completed", "tables", len(manifest.Tables), "attachments", len(manifest.Attachments), "size_bytes", size)
+	return true, s.repo.UpdateExport(ctx, *export)
+}
+
+// ExpireArchives removes the archives of exports past their expiry from
+// storage. It returns the number of archives removed.
+func (s *OffboardingService) ExpireArchives(ctx context.Context) (int, error) {
+	if s.files == nil {
+		return 0, nil
+	}
+	exports, err := s.repo.ListExpiredExports(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, export := range exports {
+		if export.StorageKey != nil {
+			if err := s.files.Delete(ctx, *export.StorageKey); err != nil {
+				s.logger.Error("Failed to delete expired export archive", "export_id", export.ID, "error", err)
+				continue
+			}
+		}
+		export.Status = types.ExportStatusExpired
+		export.StorageKey = nil
+		if err := s.repo.UpdateExport(ctx, export); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// CreateDownloadLink issues a link to download a completed export without
+// signing in. The link's token is only returned here; only its hash is kept.
+func (s *OffboardingService) CreateDownloadLink(ctx context.Context, orgID, userID, exportID uuid.UUID, req types.ExportLinkRequest) (*types.IssuedExportLink, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:export"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	lifetime := DefaultLinkLifetime
+	if req.ExpiresInHours != 0 {
+		lifetime = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if lifetime <= 0 || lifetime > MaxLinkLifetime {
+		return nil, fmt.Errorf("%w: expires_in_hours must be between 1 and %d", ErrInvalid, int(MaxLinkLifetime.Hours()))
+	}
+
+	export, err := s.repo.FindExport(ctx, orgID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != types.ExportStatusCompleted {
+		return nil, fmt.Errorf("%w: export is %s, only completed exports can be downloaded", ErrInvalid, export.Status)
+	}
+
+	now := s.now()
+	expiresAt := now.Add(lifetime)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+	link := types.ExportLink{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ExportID:       exportID,
+		TokenHash:      hashLinkToken(token),
+		ExpiresAt:      expiresAt,
+		CreatedBy:      userID,
+		CreatedAt:      now,
+	}
+	if err := s.repo.CreateLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return &types.IssuedExportLink{
+		ExportLink: link,
+		Token:      token,
+		URL:        ExportDownloadPath + token,
+	}, nil
+}
+
+// OpenDownload opens the archive a download link grants. The caller must
+// close the returned reader.
+func (s *OffboardingService) OpenDownload(ctx context.Context, token string) (*types.TenantExport, io.ReadCloser, error) {
+	if s.files == nil {
+		return nil, nil, ErrStorageUnavailable
+	}
+
+	link, err := s.repo.FindLinkByTokenHash(ctx, hashLinkToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	if !now.Before(link.ExpiresAt) {
+		return nil, nil, ErrLinkExpired
+	}
+
+	export, err := s.repo.FindExport(ctx, link.OrganizationID, link.ExportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != types.ExportStatusCompleted || export.StorageKey == nil {
+		return nil, nil, ErrLinkExpired
+	}
+
+	file, err := s.files.Download(ctx, *export.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export archive: %w", err)
+	}
+	if err := s.repo.RecordDownload(ctx, *link, now); err != nil {
+		file.Reader.Close()
+		return nil, nil, err
+	}
+
+	return export, file.Reader, nil
+}
+
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate link token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestDeletion queues the deletion of all the organization's data. The
+// request must repeat the organization's slug and name an export that was
+// downloaded, so nothing is deleted before the customer has their copy.
+func (s *OffboardingService) RequestDeletion(ctx context.Context, orgID, userID uuid.UUID, req types.TenantDeletionRequest) (*types.TenantDeletion, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:delete"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.files == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	org, err := s.repo.FindOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: organization is already deleted", ErrInvalid)
+	}
+	if req.Confirm != org.Slug {
+		return nil, fmt.Errorf("%w: confirm must be the organization's slug", ErrInvalid)
+	}
+
+	export, err := s.repo.FindExport(ctx, orgID, req.ExportID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != types.ExportStatusCompleted && export.Status != types.ExportStatusExpired {
+		return nil, fmt.Errorf("%w: export is %s, only a completed export can precede deletion", ErrInvalid, export.Status)
+	}
+	if export.FirstDownloadedAt == nil {
+		return nil, fmt.Errorf("%w: the export has not been downloaded yet", ErrInvalid)
+	}
+
+	if err := s.checkLegalHolds(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	deletion := types.TenantDeletion{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ExportID:       export.ID,
+		Status:         types.DeletionStatusPending,
+		RequestedBy:    userID,
+		CreatedAt:      s.now(),
+	}
+	if err := s.repo.CreateDeletion(ctx, deletion); err != nil {
+		if errors.Is(err, repository.ErrAlreadyQueued) {
+			return nil, fmt.Errorf("%w: the organization's deletion was already requested", ErrInvalid)
+		}
+		return nil, err
+	}
+
+	s.logger.Warn("tenant deletion requested", "organization_id", orgID, "deletion_id", deletion.ID, "requested_by", userID)
+	return &deletion, nil
+}
+
+// GetDeletion returns one of the organization's deletions
+func (s *OffboardingService) GetDeletion(ctx context.Context, orgID, id uuid.UUID) (*types.TenantDeletion, error) {
+	if err := s.authService.CheckPermission(ctx, "offboarding:delete"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	deletion, err := s.repo.FindDeletion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deletion.OrganizationID != orgID {
+		return nil, fmt.Errorf("tenant deletion %w", repository.ErrNotFound)
+	}
+	return deletion, nil
+}
+
+// VerifyCertificate returns the certificate of a completed deletion and
+// whether it is unchanged since it was issued. It needs no sign-in, so
+// whoever the certificate is handed to can check it.
+func (s *OffboardingService) VerifyCertificate(ctx context.Context, deletionID uuid.UUID) (*types.CertificateVerification, error) {
+	deletion, err := s.repo.FindDeletion(ctx, deletionID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion.Certificate == nil {
+		return nil, fmt.Errorf("deletion certificate %w", repository.ErrNotFound)
+	}
+
+	valid, signed := verifyCertificate(*deletion.Certificate, s.signingKey)
+	return &types.CertificateVerification{
+		Certificate: *deletion.Certificate,
+		Valid:       valid,
+		Signed:      signed,
+	}, nil
+}
+
+func (s *OffboardingService) checkLegalHolds(ctx context.Context, orgID uuid.UUID) error {
+	holds, err := s.repo.CountActiveLegalHolds(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if holds > 0 {
+		return fmt.Errorf("%w: the organization has %d active legal holds", ErrInvalid, holds)
+	}
+	return nil
+}
+
+// ProcessNextDeletion deletes the data and files of the organization of the
+// oldest queued deletion, checks that none are left and issues the
+// certificate. It reports whether a deletion was processed.
+func (s *OffboardingService) ProcessNextDeletion(ctx context.Context) (bool, error) {
+	deletion, err := s.repo.ClaimDeletion(ctx, s.now().Add(-DeletionStaleAfter))
+	if err != nil || deletion == nil {
+		return false, err
+	}
+	logger := s.logger.With("deletion_id", deletion.ID, "organization_id", deletion.OrganizationID)
+
+	fail := func(reason error) (bool, error) {
+		logger.Error("tenant deletion failed", "error", reason)
+		now := s.now()
+		message := reason.Error()
+		deletion.Status = types.DeletionStatusFailed
+		deletion.Error = &message
+		deletion.CompletedAt = &now
+		return true, s.repo.UpdateDeletion(ctx, *deletion)
+	}
+
+	if s.files == nil {
+		return fail(ErrStorageUnavailable)
+	}
+	org, err := s.repo.FindOrganization(ctx, deletion.OrganizationID)
+	if err != nil {
+		return fail(err)
+	}
+	export, err := s.repo.FindExport(ctx, deletion.OrganizationID, deletion.ExportID)
+	if err != nil {
+		return fail(err)
+	}
+	// A hold may have been placed since the deletion was requested
+	if err := s.checkLegalHolds(ctx, deletion.OrganizationID); err != nil {
+		return fail(err)
+	}
+
+	keys, err := s.repo.StorageKeys(ctx, deletion.OrganizationID)
+	if err != nil {
+		return fail(err)
+	}
+	tables, err := s.repo.DeleteOrganizationData(ctx, deletion.OrganizationID, s.now())
+	if err != nil {
+		return fail(err)
+	}
+
+	filesDeleted := 0
+	for _, key := range keys {
+		if err := s.files.Delete(ctx, key); err != nil {
+			// Counted as remaining when the deletion is verified below
+			logger.Error("Failed to delete organization file", "key", key, "error", err)
+			continue
+		}
+		filesDeleted++
+	}
+
+	cert, err := s.verifyDeletion(ctx, *deletion, tables, keys)
+	if err != nil {
+		return fail(err)
+	}
+	cert.OrganizationName = org.Name
+	if export.SHA256 != nil {
+		cert.ExportSHA256 = *export.SHA256
+	}
+	cert.FilesDeleted = filesDeleted
+	if err := signCertificate(cert, s.signingKey); err != nil {
+		return fail(err)
+	}
+
+	deletion.Status = types.DeletionStatusCompleted
+	deletion.Certificate = cert
+	deletion.CompletedAt = &cert.CompletedAt
+	if cert.Verified {
+		logger.Info("tenant deletion completed", "rows_deleted", cert.RowsDeleted, "files_deleted", cert.FilesDeleted)
+	} else {
+		logger.Error("tenant deletion left data behind", "rows_remaining", cert.RowsRemaining, "files_remaining", cert.FilesRemaining)
+	}
+	return true, s.repo.UpdateDeletion(ctx, *deletion)
+}
+
+// verifyDeletion counts the organization's rows and files left after a
+// deletion and drafts the certificate reporting them
+func (s *OffboardingService) verifyDeletion(ctx context.Context, deletion types.TenantDeletion, tables []types.TableDeletion, keys []string) (*types.DeletionCertificate, error) {
+	remaining, err := s.repo.CountRemainingRows(ctx, deletion.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &types.DeletionCertificate{
+		DeletionID:     deletion.ID,
+		OrganizationID: deletion.OrganizationID,
+		ExportID:       deletion.ExportID,
+		RequestedBy:    deletion.RequestedBy,
+		RequestedAt:    deletion.CreatedAt.UTC(),
+		Tables:         make([]types.TableDeletion, 0, len(tables)),
+	}
+
+	seen := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		seen[table.Table] = true
+		table.RowsRemaining = remaining[table.Table]
+		cert.Tables = append(cert.Tables, table)
+	}
+	// Tables created while the deletion ran were not emptied
+	for table, count := range remaining {
+		if !seen[table] {
+			cert.Tables = append(cert.Tables, types.TableDeletion{Table: table, RowsRemaining: count})
+		}
+	}
+	sort.Slice(cert.Tables, func(i, j int) bool { return cert.Tables[i].Table < cert.Tables[j].Table })
+
+	for _, table := range cert.Tables {
+		cert.RowsDeleted += table.RowsDeleted
+		cert.RowsRemaining += table.RowsRemaining
+	}
+
+	for _, key := range keys {
+		exists, err := s.files.Exists(ctx, key)
+		if err != nil {
+			// A file that cannot be checked cannot be certified as deleted
+			s.logger.Error("Failed to check organization file", "key", key, "error", err)
+			exists = true
+		}
+		if exists {
+			cert.FilesRemaining++
+		}
+	}
+
+	cert.Verified = cert.RowsRemaining == 0 && cert.FilesRemaining == 0
+	cert.CompletedAt = s.now().UTC()
+	return cert, nil
+}
@@ -0,0 +1,493 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/offboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOffboardingRepo struct {
+	org         types.OrganizationInfo
+	holds       int
+	rows        map[string][]string
+	attachments []types.ExportAttachment
+	keys        []string
+	deleted     []types.TableDeletion
+	remaining   map[string]int64
+
+	exports   map[uuid.UUID]*types.TenantExport
+	links     []types.ExportLink
+	downloads int
+	deletions map[uuid.UUID]*types.TenantDeletion
+	deletedAt *time.Time
+}
+
+func newFakeOffboardingRepo() *fakeOffboardingRepo {
+	return &fakeOffboardingRepo{
+		org:       types.OrganizationInfo{ID: uuid.New(), Name: "Acme Corp", Slug: "acme"},
+		rows:      make(map[string][]string),
+		exports:   make(map[uuid.UUID]*types.TenantExport),
+		deletions: make(map[uuid.UUID]*types.TenantDeletion),
+	}
+}
+
+func (f *fakeOffboardingRepo) FindOrganization(ctx context.Context, orgID uuid.UUID) (*types.OrganizationInfo, error) {
+	org := f.org
+	return &org, nil
+}
+
+func (f *fakeOffboardingRepo) CountActiveLegalHolds(ctx context.Context, orgID uuid.UUID) (int, error) {
+	return f.holds, nil
+}
+
+func (f *fakeOffboardingRepo) CreateExport(ctx context.Context, export types.TenantExport) error {
+	f.exports[export.ID] = &export
+	return nil
+}
+
+func (f *fakeOffboardingRepo) FindExport(ctx context.Context, orgID, id uuid.UUID) (*types.TenantExport, error) {
+	export, ok := f.exports[id]
+	if !ok || export.OrganizationID != orgID {
+		return nil, fmt.Errorf("tenant export %w", repository.ErrNotFound)
+	}
+	copied := *export
+	return &copied, nil
+}
+
+func (f *fakeOffboardingRepo) ListExports(ctx context.Context, orgID uuid.UUID, limit int) ([]types.TenantExport, error) {
+	return nil, nil
+}
+
+func (f *fakeOffboardingRepo) ClaimExport(ctx context.Context, staleBefore time.Time) (*types.TenantExport, error) {
+	for _, export := range f.exports {
+		if export.Status == types.ExportStatusPending {
+			export.Status = types.ExportStatusRunning
+			copied := *export
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOffboardingRepo) UpdateExport(ctx context.Context, export types.TenantExport) error {
+	f.exports[export.ID] = &export
+	return nil
+}
+
+func (f *fakeOffboardingRepo) ListExpiredExports(ctx context.Context, now time.Time) ([]types.TenantExport, error) {
+	return nil, nil
+}
+
+func (f *fakeOffboardingRepo) WriteTables(ctx context.Context, orgID uuid.UUID, open func(table *types.ExportTable) (io.Writer, error)) ([]types.ExportTable, []types.ExportAttachment, error) {
+	var tables []types.ExportTable
+	for _, name := range []string{"leads", "organizations", "widgets"} {
+		table := types.ExportTable{Table: name, Columns: []string{"id", "name"}}
+		w, err := open(&table)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range f.rows[name] {
+			fmt.Fprintln(w, row)
+			table.Rows++
+		}
+		tables = append(tables, table)
+	}
+	return tables, f.attachments, nil
+}
+
+func (f *fakeOffboardingRepo) CreateLink(ctx context.Context, link types.ExportLink) error {
+	f.links = append(f.links, link)
+	return nil
+}
+
+func (f *fakeOffboardingRepo) FindLinkByTokenHash(ctx context.Context, tokenHash string) (*types.ExportLink, error) {
+	for i := range f.links {
+		if f.links[i].TokenHash == tokenHash {
+			return &f.links[i], nil
+		}
+	}
+	return nil, fmt.Errorf("export link %w", repository.ErrNotFound)
+}
+
+func (f *fakeOffboardingRepo) RecordDownload(ctx context.Context, link types.ExportLink, at time.Time) error {
+	f.downloads++
+	if export := f.exports[link.ExportID]; export.FirstDownloadedAt == nil {
+		export.FirstDownloadedAt = &at
+	}
+	return nil
+}
+
+func (f *fakeOffboardingRepo) CreateDeletion(ctx context.Context, deletion types.TenantDeletion) error {
+	f.deletions[deletion.ID] = &deletion
+	return nil
+}
+
+func (f *fakeOffboardingRepo) FindDeletion(ctx context.Context, id uuid.UUID) (*types.TenantDeletion, error) {
+	deletion, ok := f.deletions[id]
+	if !ok {
+		return nil, fmt.Errorf("tenant deletion %w", repository.ErrNotFound)
+	}
+	copied := *deletion
+	return &copied, nil
+}
+
+func (f *fakeOffboardingRepo) ClaimDeletion(ctx context.Context, staleBefore time.Time) (*types.TenantDeletion, error) {
+	for _, deletion := range f.deletions {
+		if deletion.Status == types.DeletionStatusPending {
+			deletion.Status = types.DeletionStatusRunning
+			copied := *deletion
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateDeletion stores the certificate as JSON, as the jsonb column does
+func (f *fakeOffboardingRepo) UpdateDeletion(ctx context.Context, deletion types.TenantDeletion) error {
+	if deletion.Certificate != nil {
+		data, err := json.Marshal(deletion.Certificate)
+		if err != nil {
+			return err
+		}
+		deletion.Certificate = &types.DeletionCertificate{}
+		if err := json.Unmarshal(data, deletion.Certificate); err != nil {
+			return err
+		}
+	}
+	f.deletions[deletion.ID] = &deletion
+	return nil
+}
+
+func (f *fakeOffboardingRepo) StorageKeys(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	return f.keys, nil
+}
+
+func (f *fakeOffboardingRepo) DeleteOrganizationData(ctx context.Context, orgID uuid.UUID, deletedAt time.Time) ([]types.TableDeletion, error) {
+	f.deletedAt = &deletedAt
+	return f.deleted, nil
+}
+
+func (f *fakeOffboardingRepo) CountRemainingRows(ctx context.Context, orgID uuid.UUID) (map[string]int64, error) {
+	return f.remaining, nil
+}
+
+type fakeFileStore struct {
+	files     map[string][]byte
+	failing   map[string]bool
+	uploaded  []storage.UploadOptions
+	deletions []string
+}
+
+func newFakeFileStore() *fakeFileStore {
+	return &fakeFileStore{files: make(map[string][]byte), failing: make(map[string]bool)}
+}
+
+func (f *fakeFileStore) Upload(ctx context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error) {
+	data, err := io.ReadAll(opts.Reader)
+	if err != nil {
+		return nil, err
+	}
+	f.files[opts.Key] = data
+	f.uploaded = append(f.uploaded, opts)
+	return &storage.FileMetadata{Key: opts.Key, Size: int64(len(data))}, nil
+}
+
+func (f *fakeFileStore) Download(ctx context.Context, key string) (*storage.File, error) {
+	data, ok := f.files[key]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return &storage.File{Reader: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeFileStore) Delete(ctx context.Context, key string) error {
+	if f.failing[key] {
+		return errors.New("storage unavailable")
+	}
+	f.deletions = append(f.deletions, key)
+	delete(f.files, key)
+	return nil
+}
+
+func (f *fakeFileStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.files[key]
+	return ok, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type testClock struct{ at time.Time }
+
+func (c *testClock) now() time.Time { return c.at }
+
+func newTestService(repo *fakeOffboardingRepo, files *fakeFileStore) (*OffboardingService, *testClock) {
+	clock := &testClock{at: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	svc := NewOffboardingService(repo, allowAll{}, nil)
+	svc.now = clock.now
+	svc.SetStorage(files)
+	return svc, clock
+}
+
+// completedExport runs an export of the repo's data through the service
+func completedExport(t *testing.T, svc *OffboardingService, repo *fakeOffboardingRepo) *types.TenantExport {
+	ctx := context.Background()
+	requested, err := svc.RequestExport(ctx, repo.org.ID, uuid.New())
+	require.NoError(t, err)
+
+	processed, err := svc.ProcessNextExport(ctx)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	export, err := repo.FindExport(ctx, repo.org.ID, requested.ID)
+	require.NoError(t, err)
+	return export
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	entries := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		entries[f.Name] = content
+	}
+	return entries
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestProcessNextExportWritesDocumentedArchive(t *testing.T) {
+	repo := newFakeOffboardingRepo()
+	files := newFakeFileStore()
+	svc, _ := newTestService(repo, files)
+
+	repo.rows["leads"] = []string{`{"id": "1", "name": "Globex"}`, `{"id": "2", "name": "Initech"}`}
+	repo.rows["organizations"] = []string{`{"id": "org", "name": "Acme Corp"}`}
+	contract, lost := uuid.New(), uuid.New()
+	repo.attachments = []types.ExportAttachment{
+		{ID: contract, FileName: "../contract.pdf", StorageKey: "attachments/contract"},
+		{ID: lost, FileName: "lost.png", StorageKey: "attachments/lost"},
+	}
+	files.files["attachments/contract"] = []byte("%PDF-1.7")
+
+	export := completedExport(t, svc, repo)
+	require.Equal(t, types.ExportStatusCompleted, export.Status, export.Error)
+	require.NotNil(t, export.StorageKey)
+	require.NotNil(t, export.ExpiresAt)
+	assert.Equal(t, time.Date(2025, 9, 8, 9, 0, 0, 0, time.UTC), *export.ExpiresAt)
+
+	archive := files.files[*export.StorageKey]
+	assert.Equal(t, sha256Hex(archive), *export.SHA256)
+	assert.Equal(t, int64(len(archive)), *export.SizeBytes)
+
+	entries := readZip(t, archive)
+	assert.Equal(t, "{\"id\": \"1\", \"name\": \"Globex\"}\n{\"id\": \"2\", \"name\": \"Initech\"}\n", string(entries["data/crm/leads.jsonl"]))
+	assert.Contains(t, entries, "data/organization/organizations.jsonl")
+	assert.Contains(t, entries, "data/other/widgets.jsonl")
+	assert.Equal(t, "%PDF-1.7", string(entries["attachments/"+contract.String()+"/.._contract.pdf"]))
+	assert.Contains(t, string(entries["README.md"]), "- leads: 2 records (id, name)")
+	assert.Contains(t, string(entries["README.md"]), "1 could not be read from storage")
+
+	var manifest types.ExportManifest
+	require.NoError(t, json.Unmarshal(entries["manifest.json"], &manifest))
+	assert.Equal(t, ArchiveFormat, manifest.Format)
+	require.Len(t, manifest.Attachments, 2)
+	assert.Empty(t, manifest.Attachments[1].Path)
+	assert.NotEmpty(t, manifest.Attachments[1].Error)
+	require.Len(t, manifest.Files, len(entries)-1, "every entry but the manifest is checksummed")
+	for _, file := range manifest.Files {
+		assert.Equal(t, sha256Hex(entries[file.Path]), file.SHA256, file.Path)
+		assert.Equal(t, int64(len(entries[file.Path])), file.SizeBytes, file.Path)
+	}
+}
+
+func TestDownloadLinkKeepsOnlyTokenHashAndExpires(t *testing.T) {
+	repo := newFakeOffboardingRepo()
+	files := newFakeFileStore()
+	svc, clock := newTestService(repo, files)
+	ctx := context.Background()
+	export := completedExport(t, svc, repo)
+
+	_, err := svc.CreateDownloadLink(ctx, repo.org.ID, uuid.New(), export.ID, types.ExportLinkRequest{ExpiresInHours: 24 * 30})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	link, err := svc.CreateDownloadLink(ctx, repo.org.ID, uuid.New(), export.ID, types.ExportLinkRequest{ExpiresInHours: 2})
+	require.NoError(t, err)
+	assert.Equal(t, ExportDownloadPath+link.Token, link.URL)
+	require.Len(t, repo.links, 1)
+	assert.NotEqual(t, link.Token, repo.links[0].TokenHash)
+	assert.Equal(t, sha256Hex([]byte(link.Token)), repo.links[0].TokenHash)
+
+	_, _, err = svc.OpenDownload(ctx, "not-a-token")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	downloaded, archive, err := svc.OpenDownload(ctx, link.Token)
+	require.NoError(t, err)
+	data, err := io.ReadAll(archive)
+	require.NoError(t, err)
+	archive.Close()
+	assert.Equal(t, *downloaded.SHA256, sha256Hex(data))
+	assert.Equal(t, 1, repo.downloads)
+	assert.NotNil(t, repo.exports[export.ID].FirstDownloadedAt)
+
+	clock.at = clock.at.Add(2 * time.Hour)
+	_, _, err = svc.OpenDownload(ctx, link.Token)
+	assert.ErrorIs(t, err, ErrLinkExpired)
+}
+
+func TestRequestDeletionNeedsSlugAndDownloadedExport(t *testing.T) {
+	repo := newFakeOffboardingRepo()
+	files := newFakeFileStore()
+	svc, _ := newTestService(repo, files)
+	ctx := context.Background()
+	export := completedExport(t, svc, repo)
+	orgID, userID := repo.org.ID, uuid.New()
+
+	_, err := svc.RequestDeletion(ctx, orgID, userID, types.TenantDeletionRequest{ExportID: export.ID, Confirm: "acme"})
+	assert.ErrorIs(t, err, ErrInvalid, "the export was not downloaded")
+
+	link, err := svc.CreateDownloadLink(ctx, orgID, userID, export.ID, types.ExportLinkRequest{})
+	require.NoError(t, err)
+	_, archive, err := svc.OpenDownload(ctx, link.Token)
+	require.NoError(t, err)
+	archive.Close()
+
+	_, err = svc.RequestDeletion(ctx, orgID, userID, types.TenantDeletionRequest{ExportID: export.ID, Confirm: "Acme Corp"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	repo.holds = 1
+	_, err = svc.RequestDeletion(ctx, orgID, userID, types.TenantDeletionRequest{ExportID: export.ID, Confirm: "acme"})
+	assert.ErrorIs(t, err, ErrInvalid, "a legal hold blocks deletion")
+
+	repo.holds = 0
+	deletion, err := svc.RequestDeletion(ctx, orgID, userID, types.TenantDeletionRequest{ExportID: export.ID, Confirm: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, types.DeletionStatusPending, deletion.Status)
+	assert.Nil(t, repo.deletedAt, "data is deleted in the background")
+}
+
+func queueDeletion(t *testing.T, svc *OffboardingService, repo *fakeOffboardingRepo) *types.TenantDeletion {
+	ctx := context.Background()
+	export := completedExport(t, svc, repo)
+	now := svc.now()
+	repo.exports[export.ID].FirstDownloadedAt = &now
+
+	deletion, err := svc.RequestDeletion(ctx, repo.org.ID, uuid.New(), types.TenantDeletionRequest{ExportID: export.ID, Confirm: "acme"})
+	require.NoError(t, err)
+	return deletion
+}
+
+func TestProcessNextDeletionIssuesVerifiedCertificate(t *testing.T) {
+	repo := newFakeOffboardingRepo()
+	files := newFakeFileStore()
+	svc, _ := newTestService(repo, files)
+	svc.SetSigningKey([]byte("certificate-key"))
+	ctx := context.Background()
+
+	deletion := queueDeletion(t, svc, repo)
+	files.files["attachments/contract"] = []byte("%PDF-1.7")
+	repo.keys = []string{"attachments/contract"}
+	for key := range files.files {
+		if key != "attachments/contract" {
+			repo.keys = append(repo.keys, key)
+		}
+	}
+	repo.deleted = []types.TableDeletion{{Table: "leads", RowsDeleted: 2}, {Table: "attachments", RowsDeleted: 1}}
+
+	processed, err := svc.ProcessNextDeletion(ctx)
+	require.NoError(t, err)
+	require.True(t, processed)
+	assert.NotNil(t, repo.deletedAt)
+	assert.Empty(t, files.files)
+
+	stored := repo.deletions[deletion.ID]
+	assert.Equal(t, types.DeletionStatusCompleted, stored.Status)
+	cert := stored.Certificate
+	require.NotNil(t, cert)
+	assert.True(t, cert.Verified)
+	assert.Equal(t, "Acme Corp", cert.OrganizationName)
+	assert.Equal(t, int64(3), cert.RowsDeleted)
+	assert.Equal(t, 2, cert.FilesDeleted)
+	assert.Equal(t, "attachments", cert.Tables[0].Table)
+	assert.NotEmpty(t, cert.ExportSHA256)
+	assert.NotEmpty(t, cert.Signature)
+
+	verification, err := svc.VerifyCertificate(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.True(t, verification.Signed)
+
+	cert.RowsDeleted = 30
+	verification, err = svc.VerifyCertificate(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid, "an altered certificate fails verification")
+}
+
+func TestProcessNextDeletionReportsWhatRemains(t *testing.T) {
+	repo := newFakeOffboardingRepo()
+	files := newFakeFileStore()
+	svc, _ := newTestService(repo, files)
+	ctx := context.Background()
+
+	deletion := queueDeletion(t, svc, repo)
+	files.files["attachments/contract"] = []byte("%PDF-1.7")
+	files.failing["attachments/contract"] = true
+	repo.keys = []string{"attachments/contract"}
+	repo.deleted = []types.TableDeletion{{Table: "leads", RowsDeleted: 2}}
+	repo.remaining = map[string]int64{"leads": 1, "new_table": 4}
+
+	processed, err := svc.ProcessNextDeletion(ctx)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	cert := repo.deletions[deletion.ID].Certificate
+	require.NotNil(t, cert)
+	assert.False(t, cert.Verified)
+	assert.Equal(t, int64(5), cert.RowsRemaining)
+	assert.Equal(t, 1, cert.FilesRemaining)
+	assert.Equal(t, 0, cert.FilesDeleted)
+	assert.Equal(t, []types.TableDeletion{
+		{Table: "leads", RowsDeleted: 2, RowsRemaining: 1},
+		{Table: "new_table", RowsRemaining: 4},
+	}, cert.Tables)
+
+	verification, err := svc.VerifyCertificate(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.False(t, verification.Signed)
+}
+
+func TestArchiveModuleGroupsTablesByPrefix(t *testing.T) {
+	assert.Equal(t, "crm", archiveModule("lead_stage_history"))
+	assert.Equal(t, "sales", archiveModule("sales_orders"))
+	assert.Equal(t, "accounting", archiveModule("invoice_lines"))
+	assert.Equal(t, "organization", archiveModule("organization_users"))
+	assert.Equal(t, "other", archiveModule("widgets"))
+	assert.Equal(t, "file", archiveFileName(".."))
+	assert.Equal(t, "a_b.txt", archiveFileName("a/b.txt"))
+}
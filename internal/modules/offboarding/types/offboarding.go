@@ -0,0 +1,182 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportStatus is the state of a tenant export
+type ExportStatus string
+
+const (
+	ExportStatusPending   ExportStatus = "pending"
+	ExportStatusRunning   ExportStatus = "running"
+	ExportStatusCompleted ExportStatus = "completed"
+	ExportStatusFailed    ExportStatus = "failed"
+	// ExportStatusExpired exports had their archive removed from storage
+	ExportStatusExpired ExportStatus = "expired"
+)
+
+// TenantExport is a full export of an organization's data as a zip archive
+type TenantExport struct {
+	ID                uuid.UUID       `json:"id"`
+	OrganizationID    uuid.UUID       `json:"organization_id"`
+	Status            ExportStatus    `json:"status"`
+	StorageKey        *string         `json:"-"`
+	SizeBytes         *int64          `json:"size_bytes,omitempty"`
+	SHA256            *string         `json:"sha256,omitempty"`
+	Manifest          *ExportManifest `json:"manifest,omitempty"`
+	Error             *string         `json:"error,omitempty"`
+	RequestedBy       uuid.UUID       `json:"requested_by"`
+	CreatedAt         time.Time       `json:"created_at"`
+	StartedAt         *time.Time      `json:"started_at,omitempty"`
+	CompletedAt       *time.Time      `json:"completed_at,omitempty"`
+	ExpiresAt         *time.Time      `json:"expires_at,omitempty"`
+	FirstDownloadedAt *time.Time      `json:"first_downloaded_at,omitempty"`
+}
+
+// ExportManifest describes the content of an export archive. It is written
+// to the archive as manifest.json.
+type ExportManifest struct {
+	Format         string               `json:"format"`
+	ExportID       uuid.UUID            `json:"export_id"`
+	OrganizationID uuid.UUID            `json:"organization_id"`
+	GeneratedAt    time.Time            `json:"generated_at"`
+	Tables         []ExportTable        `json:"tables"`
+	Attachments    []ExportAttachment   `json:"attachments"`
+	Files          []ExportManifestFile `json:"files"`
+}
+
+// ExportTable is one table written to an export
+type ExportTable struct {
+	Table   string   `json:"table"`
+	Module  string   `json:"module"`
+	Path    string   `json:"path"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+// ExportAttachment is an attachment file of the organization. Path is empty
+// when the file could not be read from storage, as Error explains.
+type ExportAttachment struct {
+	ID         uuid.UUID `json:"id"`
+	FileName   string    `json:"file_name"`
+	StorageKey string    `json:"-"`
+	Path       string    `json:"path,omitempty"`
+	SizeBytes  int64     `json:"size_bytes,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ExportManifestFile is the checksum of one file of an export archive
+type ExportManifestFile struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256"`
+}
+
+// ExportLinkRequest issues a download link for an export
+type ExportLinkRequest struct {
+	// ExpiresInHours defaults to 24 and cannot outlive the archive
+	ExpiresInHours int `json:"expires_in_hours,omitempty"`
+}
+
+// ExportLink grants download of an export archive without signing in
+type ExportLink struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	ExportID       uuid.UUID  `json:"export_id"`
+	TokenHash      string     `json:"-"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedBy      uuid.UUID  `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DownloadCount  int        `json:"download_count"`
+	LastDownloaded *time.Time `json:"last_downloaded_at,omitempty"`
+}
+
+// IssuedExportLink is a newly issued download link. The token is only ever
+// returned here.
+type IssuedExportLink struct {
+	ExportLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// DeletionStatus is the state of a tenant deletion
+type DeletionStatus string
+
+const (
+	DeletionStatusPending   DeletionStatus = "pending"
+	DeletionStatusRunning   DeletionStatus = "running"
+	DeletionStatusCompleted DeletionStatus = "completed"
+	DeletionStatusFailed    DeletionStatus = "failed"
+)
+
+// TenantDeletionRequest asks for the deletion of the organization's data.
+// Confirm must repeat the organization's slug.
+type TenantDeletionRequest struct {
+	ExportID uuid.UUID `json:"export_id"`
+	Confirm  string    `json:"confirm"`
+}
+
+// TenantDeletion is the deletion of every record and file of an organization
+type TenantDeletion struct {
+	ID             uuid.UUID            `json:"id"`
+	OrganizationID uuid.UUID            `json:"organization_id"`
+	ExportID       uuid.UUID            `json:"export_id"`
+	Status         DeletionStatus       `json:"status"`
+	RequestedBy    uuid.UUID            `json:"requested_by"`
+	CreatedAt      time.Time            `json:"created_at"`
+	StartedAt      *time.Time           `json:"started_at,omitempty"`
+	CompletedAt    *time.Time           `json:"completed_at,omitempty"`
+	Certificate    *DeletionCertificate `json:"certificate,omitempty"`
+	Error          *string              `json:"error,omitempty"`
+}
+
+// TableDeletion reports the rows deleted from one table and the rows of the
+// organization found in it afterwards
+type TableDeletion struct {
+	Table         string `json:"table"`
+	RowsDeleted   int64  `json:"rows_deleted"`
+	RowsRemaining int64  `json:"rows_remaining"`
+}
+
+// DeletionCertificate attests what a tenant deletion removed and that
+// nothing of the organization was found afterwards. Digest is the SHA-256 of
+// the certificate's JSON without Digest and Signature; Signature is the
+// HMAC-SHA256 of the digest when a signing key is configured.
+type DeletionCertificate struct {
+	DeletionID       uuid.UUID       `json:"deletion_id"`
+	OrganizationID   uuid.UUID       `json:"organization_id"`
+	OrganizationName string          `json:"organization_name"`
+	ExportID         uuid.UUID       `json:"export_id"`
+	ExportSHA256     string          `json:"export_sha256"`
+	RequestedBy      uuid.UUID       `json:"requested_by"`
+	RequestedAt      time.Time       `json:"requested_at"`
+	CompletedAt      time.Time       `json:"completed_at"`
+	Tables           []TableDeletion `json:"tables"`
+	RowsDeleted      int64           `json:"rows_deleted"`
+	RowsRemaining    int64           `json:"rows_remaining"`
+	FilesDeleted     int             `json:"files_deleted"`
+	FilesRemaining   int             `json:"files_remaining"`
+	Verified         bool            `json:"verified"`
+	Digest           string          `json:"digest,omitempty"`
+	Signature        string          `json:"signature,omitempty"`
+}
+
+// CertificateVerification is a deletion certificate with the result of
+// checking its digest and signature
+type CertificateVerification struct {
+	Certificate DeletionCertificate `json:"certificate"`
+	Valid       bool                `json:"valid"`
+	// Signed reports whether the signature was checked against the signing key
+	Signed bool `json:"signed"`
+}
+
+// OrganizationInfo is what offboarding needs to know about an organization
+type OrganizationInfo struct {
+	ID        uuid.UUID
+	Name      string
+	Slug      string
+	DeletedAt *time.Time
+}
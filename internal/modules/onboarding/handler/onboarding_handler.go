@@ -0,0 +1,457 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OnboardingHandler handles onboarding templates, workflows, their tasks
+// and equipment assignments
+type OnboardingHandler struct {
+	service *service.OnboardingService
+}
+
+func NewOnboardingHandler(service *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{service: service}
+}
+
+func (h *OnboardingHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/onboarding-templates", h.ListTemplates)
+	router.POST("/api/onboarding-templates", h.CreateTemplate)
+	router.GET("/api/onboarding-templates/:id", h.GetTemplate)
+	router.PUT("/api/onboarding-templates/:id", h.UpdateTemplate)
+	router.DELETE("/api/onboarding-templates/:id", h.DeleteTemplate)
+
+	router.GET("/api/onboarding-workflows", h.ListWorkflows)
+	router.POST("/api/onboarding-workflows", h.StartWorkflow)
+	router.GET("/api/onboarding-workflows/:id", h.GetWorkflow)
+	router.POST("/api/onboarding-workflows/:id/cancel", h.CancelWorkflow)
+	router.POST("/api/onboarding-workflows/:id/transfer-ownership", h.TransferOwnership)
+
+	router.GET("/api/onboarding-tasks", h.ListTasks)
+	router.POST("/api/onboarding-tasks/:id/complete", h.CompleteTask)
+	router.POST("/api/onboarding-tasks/:id/provision", h.RetryProvisioning)
+
+	router.GET("/api/equipment-assignments", h.ListEquipment)
+	router.POST("/api/equipment-assignments/:id/return", h.ReturnEquipment)
+}
+
+// ListTemplates handles GET /api/onboarding-templates with an optional kind filter
+func (h *OnboardingHandler) ListTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	templates, err := h.service.ListTemplates(r.Context(), authCtx.OrganizationID, types.Kind(r.URL.Query().Get("kind")))
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// CreateTemplate handles POST /api/onboarding-templates
+func (h *OnboardingHandler) CreateTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.CreateTemplate(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplate handles GET /api/onboarding-templates/:id
+func (h *OnboardingHandler) GetTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.GetTemplate(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// UpdateTemplate handles PUT /api/onboarding-templates/:id
+func (h *OnboardingHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /api/onboarding-templates/:id
+func (h *OnboardingHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTemplate(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWorkflows handles GET /api/onboarding-workflows with optional
+// employee_id, kind and state filters
+func (h *OnboardingHandler) ListWorkflows(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.WorkflowFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Kind:           types.Kind(q.Get("kind")),
+		State:          types.WorkflowState(q.Get("state")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"employee_id": &filter.EmployeeID}) {
+		return
+	}
+
+	workflows, err := h.service.ListWorkflows(r.Context(), filter)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflows)
+}
+
+// StartWorkflow handles POST /api/onboarding-workflows, starting a
+// workflow without waiting for an HR event
+func (h *OnboardingHandler) StartWorkflow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.StartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.service.StartWorkflow(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, workflow)
+}
+
+// GetWorkflow handles GET /api/onboarding-workflows/:id
+func (h *OnboardingHandler) GetWorkflow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid workflow ID", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.service.GetWorkflow(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, workflow)
+}
+
+// CancelWorkflow handles POST /api/onboarding-workflows/:id/cancel
+func (h *OnboardingHandler) CancelWorkflow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid workflow ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CancelWorkflow(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TransferOwnership handles POST /api/onboarding-workflows/:id/transfer-ownership
+func (h *OnboardingHandler) TransferOwnership(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid workflow ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.TransferOwnership(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transfer)
+}
+
+// ListTasks handles GET /api/onboarding-tasks, listing the open tasks of
+// open workflows. assignee filters by user, with "me" for the caller;
+// all=true includes finished tasks.
+func (h *OnboardingHandler) ListTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.TaskFilter{
+		OrganizationID: authCtx.OrganizationID,
+		OpenOnly:       q.Get("all") != "true",
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	switch v := q.Get("assignee"); v {
+	case "":
+	case "me":
+		filter.AssigneeUserID = &authCtx.UserID
+	default:
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid assignee", http.StatusBadRequest)
+			return
+		}
+		filter.AssigneeUserID = &id
+	}
+
+	tasks, err := h.service.ListTasks(r.Context(), filter)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// CompleteTask handles POST /api/onboarding-tasks/:id/complete
+func (h *OnboardingHandler) CompleteTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.TaskCompletion
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := h.service.CompleteTask(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+// RetryProvisioning handles POST /api/onboarding-tasks/:id/provision,
+// running a pending or failed provisioning task again
+func (h *OnboardingHandler) RetryProvisioning(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.service.RetryProvisioning(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+// ListEquipment handles GET /api/equipment-assignments with optional
+// employee_id and open=true filters
+func (h *OnboardingHandler) ListEquipment(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.EquipmentFilter{
+		OrganizationID: authCtx.OrganizationID,
+		OpenOnly:       r.URL.Query().Get("open") == "true",
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"employee_id": &filter.EmployeeID}) {
+		return
+	}
+
+	equipment, err := h.service.ListEquipment(r.Context(), filter)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, equipment)
+}
+
+// ReturnEquipment handles POST /api/equipment-assignments/:id/return
+func (h *OnboardingHandler) ReturnEquipment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid equipment assignment ID", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.service.ReturnEquipment(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, assignment)
+}
+
+// queryUUIDs parses optional ID query parameters
+func queryUUIDs(w http.ResponseWriter, r *http.Request, params map[string]**uuid.UUID) bool {
+	q := r.URL.Query()
+	for name, dest := range params {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return false
+			}
+			*dest = &id
+		}
+	}
+	return true
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeOnboardingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,117 @@
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OnboardingModule represents the employee onboarding and offboarding module
+type OnboardingModule struct {
+	onboardingService *service.OnboardingService
+	onboardingHandler *handler.OnboardingHandler
+	logger            *slog.Logger
+}
+
+// NewOnboardingModule creates a new onboarding module
+func NewOnboardingModule() *OnboardingModule {
+	return &OnboardingModule{}
+}
+
+// Name returns the module name
+func (m *OnboardingModule) Name() string {
+	return "onboarding"
+}
+
+// Init initializes the onboarding module
+func (m *OnboardingModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "onboarding")
+	m.logger.Info("Initializing onboarding module")
+
+	// Create repositories
+	onboardingRepo := repository.NewOnboardingRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.onboardingService = service.NewOnboardingService(onboardingRepo, authAdapter, deps.EventBus, m.logger)
+
+	// Create handlers
+	m.onboardingHandler = handler.NewOnboardingHandler(m.onboardingService)
+
+	m.logger.Info("Onboarding module initialized successfully")
+	return nil
+}
+
+// OnboardingService returns the onboarding service, so integrations can
+// register provisioners for the systems they manage accounts in
+func (m *OnboardingModule) OnboardingService() *service.OnboardingService {
+	return m.onboardingService
+}
+
+// RegisterRoutes registers onboarding module routes
+func (m *OnboardingModule) RegisterRoutes(router interface{}) {
+	if m.onboardingHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.onboardingHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers starts onboarding when an employee is hired and
+// offboarding when one is terminated
+func (m *OnboardingModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.onboardingService == nil {
+		return
+	}
+
+	eventBus.Subscribe(service.EventEmployeeHired, m.handleEmployeeEvent(types.KindOnboarding))
+	eventBus.Subscribe(service.EventEmployeeTerminated, m.handleEmployeeEvent(types.KindOffboarding))
+
+	m.logger.Info("Onboarding module event handlers registered")
+}
+
+// handleEmployeeEvent starts the workflow of the kind for the employee in
+// the event. Failures are logged rather than returned so they never fail
+// the HR change that raised the event.
+func (m *OnboardingModule) handleEmployeeEvent(kind types.Kind) events.HandlerFunc {
+	return func(ctx context.Context, event events.Event) error {
+		// The payload might be the struct itself or a map, depending on how it was published
+		var payload types.EmployeeEvent
+		bytes, err := json.Marshal(event.Payload)
+		if err == nil {
+			err = json.Unmarshal(bytes, &payload)
+		}
+		if err != nil {
+			m.logger.Error("Failed to decode employee event", "event", event.Type, "error", err)
+			return nil
+		}
+
+		workflow, err := m.onboardingService.HandleEmployeeEvent(ctx, kind, payload)
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			m.logger.Info("Employee already has an open workflow", "kind", kind, "employee_id", payload.EmployeeID)
+		case err != nil:
+			m.logger.Error("Failed to start workflow", "kind", kind, "employee_id", payload.EmployeeID, "error", err)
+		case workflow != nil:
+			m.logger.Info("Workflow started from employee event", "kind", kind, "workflow_id", workflow.ID)
+		}
+		return nil
+	}
+}
+
+// Health checks the health of the onboarding module
+func (m *OnboardingModule) Health() error {
+	return nil
+}
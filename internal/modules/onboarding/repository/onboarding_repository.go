@@ -0,0 +1,782 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a template, employee, workflow, task,
+	// department or equipment assignment does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a template name is already used, or the
+	// employee already has an open workflow of the kind
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when the workflow or task state does not
+	// allow the change
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// OnboardingRepo defines the interface for onboarding repository operations
+type OnboardingRepo interface {
+	ListTemplates(ctx context.Context, orgID uuid.UUID, kind types.Kind) ([]types.Template, error)
+	FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error)
+	FindDefaultTemplate(ctx context.Context, orgID uuid.UUID, kind types.Kind, departmentID *uuid.UUID) (*types.Template, error)
+	CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, request types.TemplateRequest) (*types.Template, error)
+	UpdateTemplate(ctx context.Context, orgID, userID, id uuid.UUID, request types.TemplateRequest) (*types.Template, error)
+	DeleteTemplate(ctx context.Context, orgID, id uuid.UUID) error
+	FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error)
+	CreateWorkflow(ctx context.Context, userID *uuid.UUID, workflow types.Workflow) (*types.Workflow, error)
+	ListWorkflows(ctx context.Context, filter types.WorkflowFilter) ([]types.Workflow, error)
+	FindWorkflow(ctx context.Context, orgID, id uuid.UUID) (*types.Workflow, error)
+	CancelWorkflow(ctx context.Context, orgID, id uuid.UUID) error
+	CompleteWorkflow(ctx context.Context, orgID, id uuid.UUID) (bool, error)
+	ListTasks(ctx context.Context, filter types.TaskFilter) ([]types.Task, error)
+	FindTask(ctx context.Context, orgID, id uuid.UUID) (*types.Task, error)
+	SaveTask(ctx context.Context, userID *uuid.UUID, task types.Task, issue *types.EquipmentAssignment, returnEquipment bool) (*types.Task, error)
+	TransferOwnership(ctx context.Context, orgID, workflowID, fromUserID, toUserID uuid.UUID, userID *uuid.UUID) (*types.OwnershipTransfer, error)
+	ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.EquipmentAssignment, error)
+	ReturnEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.EquipmentAssignment, error)
+}
+
+// OnboardingRepository stores onboarding and offboarding checklists, their
+// workflows and equipment assignments, and moves CRM ownership on offboarding
+type OnboardingRepository struct {
+	db *sql.DB
+}
+
+// Ensure OnboardingRepository implements OnboardingRepo interface
+var _ OnboardingRepo = &OnboardingRepository{}
+
+func NewOnboardingRepository(db *sql.DB) *OnboardingRepository {
+	return &OnboardingRepository{db: db}
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func checkDepartment(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM departments WHERE id = $1 AND organization_id = $2)
+	`, *id, orgID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check department: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("department %w", ErrNotFound)
+	}
+	return nil
+}
+
+const templateColumns = `id, organization_id, name, kind, department_id, active, created_at, updated_at`
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*types.Template, error) {
+	var t types.Template
+	var departmentID uuid.NullUUID
+	if err := row.Scan(&t.ID, &t.OrganizationID, &t.Name, &t.Kind, &departmentID, &t.Active, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.DepartmentID = nullUUID(departmentID)
+	t.Tasks = []types.TemplateTask{}
+	return &t, nil
+}
+
+// ListTemplates returns the templates of an organization with their tasks
+func (r *OnboardingRepository) ListTemplates(ctx context.Context, orgID uuid.UUID, kind types.Kind) ([]types.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM onboarding_templates WHERE organization_id = $1`
+	args := []interface{}{orgID}
+	if kind != "" {
+		query += ` AND kind = $2`
+		args = append(args, string(kind))
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY kind, name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []types.Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		if templates[i].Tasks, err = r.templateTasks(ctx, templates[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+func (r *OnboardingRepository) templateTasks(ctx context.Context, templateID uuid.UUID) ([]types.TemplateTask, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, sequence, name, COALESCE(description, ''), task_type, assignee, assignee_user_id,
+			due_offset_days, required, COALESCE(provisioning_system, ''), COALESCE(equipment_name, '')
+		FROM onboarding_template_tasks WHERE template_id = $1
+		ORDER BY sequence, name
+	`, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list template tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []types.TemplateTask{}
+	for rows.Next() {
+		var t types.TemplateTask
+		var assigneeUserID uuid.NullUUID
+		if err := rows.Scan(&t.ID, &t.Sequence, &t.Name, &t.Description, &t.TaskType, &t.Assignee, &assigneeUserID,
+			&t.DueOffsetDays, &t.Required, &t.ProvisioningSystem, &t.EquipmentName); err != nil {
+			return nil, fmt.Errorf("failed to scan template task: %w", err)
+		}
+		t.AssigneeUserID = nullUUID(assigneeUserID)
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func (r *OnboardingRepository) FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error) {
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, `
+		SELECT `+templateColumns+` FROM onboarding_templates WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("template %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find template: %w", err)
+	}
+	if t.Tasks, err = r.templateTasks(ctx, t.ID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// FindDefaultTemplate returns the active template of the kind for the
+// department, falling back to one without a department
+func (r *OnboardingRepository) FindDefaultTemplate(ctx context.Context, orgID uuid.UUID, kind types.Kind, departmentID *uuid.UUID) (*types.Template, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM onboarding_templates
+		WHERE organization_id = $1 AND kind = $2 AND active AND (department_id IS NULL OR department_id = $3)
+		ORDER BY department_id IS NULL, updated_at DESC
+		LIMIT 1
+	`, orgID, string(kind), departmentID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s template %w", kind, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find default template: %w", err)
+	}
+	return r.FindTemplate(ctx, orgID, id)
+}
+
+func (r *OnboardingRepository) CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	return r.saveTemplate(ctx, orgID, userID, nil, request)
+}
+
+// UpdateTemplate replaces a template and its tasks. Workflows already
+// started keep the tasks they were started with.
+func (r *OnboardingRepository) UpdateTemplate(ctx context.Context, orgID, userID, id uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	return r.saveTemplate(ctx, orgID, userID, &id, request)
+}
+
+func (r *OnboardingRepository) saveTemplate(ctx context.Context, orgID, userID uuid.UUID, id *uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkDepartment(ctx, tx, orgID, request.DepartmentID); err != nil {
+		return nil, err
+	}
+	active := request.Active == nil || *request.Active
+
+	var templateID uuid.UUID
+	if id == nil {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO onboarding_templates (organization_id, name, kind, department_id, active, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $6)
+			RETURNING id
+		`, orgID, request.Name, string(request.Kind), request.DepartmentID, active, userID).Scan(&templateID)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			UPDATE onboarding_templates SET name = $3, kind = $4, department_id = $5, active = $6, updated_by = $7,
+				updated_at = now()
+			WHERE organization_id = $1 AND id = $2
+			RETURNING id
+		`, orgID, *id, request.Name, string(request.Kind), request.DepartmentID, active, userID).Scan(&templateID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("template %w", ErrNotFound)
+	}
+	if err != nil {
+		if isUniqueViolation(err, "onboarding_templates_name_unique") {
+			return nil, fmt.Errorf("template %s %w", request.Name, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM onboarding_template_tasks WHERE template_id = $1`, templateID); err != nil {
+		return nil, fmt.Errorf("failed to clear template tasks: %w", err)
+	}
+	for _, t := range request.Tasks {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO onboarding_template_tasks (
+				template_id, sequence, name, description, task_type, assignee, assignee_user_id, due_offset_days,
+				required, provisioning_system, equipment_name
+			) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''))
+		`, templateID, t.Sequence, t.Name, t.Description, string(t.TaskType), string(t.Assignee), t.AssigneeUserID,
+			t.DueOffsetDays, t.Required == nil || *t.Required, t.ProvisioningSystem, t.EquipmentName); err != nil {
+			return nil, fmt.Errorf("failed to save template task: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit template: %w", err)
+	}
+	return r.FindTemplate(ctx, orgID, templateID)
+}
+
+// DeleteTemplate removes a template; workflows started from it keep their tasks
+func (r *OnboardingRepository) DeleteTemplate(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM onboarding_templates WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("template %w", ErrNotFound)
+	}
+	return nil
+}
+
+// FindEmployee returns an employee with the user of their manager, or of
+// their department's manager when they report to no one
+func (r *OnboardingRepository) FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error) {
+	var e types.Employee
+	var userID, managerUserID, departmentID uuid.NullUUID
+	var hired, terminated sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT e.id, e.organization_id, e.name, e.user_id, COALESCE(m.user_id, dm.user_id), e.department_id,
+			e.date_hired, e.date_terminated
+		FROM employees e
+		LEFT JOIN employees m ON m.id = e.parent_id AND m.deleted_at IS NULL
+		LEFT JOIN departments d ON d.id = e.department_id
+		LEFT JOIN employees dm ON dm.id = d.manager_id AND dm.id <> e.id AND dm.deleted_at IS NULL
+		WHERE e.organization_id = $1 AND e.id = $2
+	`, orgID, id).Scan(&e.ID, &e.OrganizationID, &e.Name, &userID, &managerUserID, &departmentID, &hired, &terminated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("employee %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find employee: %w", err)
+	}
+	e.UserID = nullUUID(userID)
+	e.ManagerUserID = nullUUID(managerUserID)
+	e.DepartmentID = nullUUID(departmentID)
+	if hired.Valid {
+		e.DateHired = &hired.Time
+	}
+	if terminated.Valid {
+		e.DateTerminated = &terminated.Time
+	}
+	return &e, nil
+}
+
+// CreateWorkflow stores a workflow with its tasks
+func (r *OnboardingRepository) CreateWorkflow(ctx context.Context, userID *uuid.UUID, workflow types.Workflow) (*types.Workflow, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO onboarding_workflows (organization_id, employee_id, template_id, kind, start_date, created_by)
+		VALUES ($1, $2, $3, $4, $5::date, $6)
+		RETURNING id
+	`, workflow.OrganizationID, workflow.EmployeeID, workflow.TemplateID, string(workflow.Kind), workflow.StartDate,
+		userID).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err, "onboarding_workflows_open_unique") {
+			return nil, fmt.Errorf("open %s workflow for this employee %w", workflow.Kind, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create workflow: %w", err)
+	}
+	for _, t := range workflow.Tasks {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO onboarding_tasks (
+				organization_id, workflow_id, sequence, name, description, task_type, assignee_user_id, due_date,
+				required, provisioning_system, equipment_name, equipment_assignment_id
+			) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8::date, $9, NULLIF($10, ''), NULLIF($11, ''), $12)
+		`, workflow.OrganizationID, id, t.Sequence, t.Name, t.Description, string(t.TaskType), t.AssigneeUserID,
+			t.DueDate, t.Required, t.ProvisioningSystem, t.EquipmentName, t.EquipmentAssignmentID); err != nil {
+			return nil, fmt.Errorf("failed to create workflow task: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit workflow: %w", err)
+	}
+	return r.FindWorkflow(ctx, workflow.OrganizationID, id)
+}
+
+const workflowColumns = `
+	w.id, w.organization_id, w.employee_id, e.name, w.template_id, w.kind, w.state, w.start_date,
+	w.ownership_user_id, w.ownership_contacts, w.ownership_leads, w.ownership_activities,
+	w.ownership_transferred_at, w.completed_at, w.created_at, w.updated_at
+`
+
+const workflowFrom = ` FROM onboarding_workflows w JOIN employees e ON e.id = w.employee_id `
+
+func scanWorkflow(row interface{ Scan(...interface{}) error }) (*types.Workflow, error) {
+	var w types.Workflow
+	var templateID, ownershipUserID uuid.NullUUID
+	var ownership types.OwnershipTransfer
+	var transferredAt, completedAt sql.NullTime
+	err := row.Scan(&w.ID, &w.OrganizationID, &w.EmployeeID, &w.EmployeeName, &templateID, &w.Kind, &w.State,
+		&w.StartDate, &ownershipUserID, &ownership.Contacts, &ownership.Leads, &ownership.Activities, &transferredAt,
+		&completedAt, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.TemplateID = nullUUID(templateID)
+	if ownershipUserID.Valid && transferredAt.Valid {
+		ownership.ToUserID = ownershipUserID.UUID
+		ownership.TransferredAt = transferredAt.Time
+		w.Ownership = &ownership
+	}
+	if completedAt.Valid {
+		w.CompletedAt = &completedAt.Time
+	}
+	w.Tasks = []types.Task{}
+	return &w, nil
+}
+
+// ListWorkflows returns a page of workflows with their tasks, most recent
+// start first
+func (r *OnboardingRepository) ListWorkflows(ctx context.Context, filter types.WorkflowFilter) ([]types.Workflow, error) {
+	where := []string{"w.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.EmployeeID != nil {
+		add("w.employee_id = $%d", *filter.EmployeeID)
+	}
+	if filter.Kind != "" {
+		add("w.kind = $%d", string(filter.Kind))
+	}
+	if filter.State != "" {
+		add("w.state = $%d", string(filter.State))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+workflowColumns+workflowFrom+` WHERE `+strings.Join(where, " AND ")+
+		fmt.Sprintf(` ORDER BY w.start_date DESC, e.name LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	defer rows.Close()
+
+	workflows := []types.Workflow{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		w, err := scanWorkflow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workflow: %w", err)
+		}
+		index[w.ID] = len(workflows)
+		workflows = append(workflows, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(workflows) == 0 {
+		return workflows, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(workflows))
+	for _, w := range workflows {
+		ids = append(ids, w.ID)
+	}
+	tasks, err := r.queryTasks(ctx, `t.workflow_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		w := &workflows[index[t.WorkflowID]]
+		w.Tasks = append(w.Tasks, t)
+	}
+	return workflows, nil
+}
+
+// FindWorkflow returns a workflow with its tasks
+func (r *OnboardingRepository) FindWorkflow(ctx context.Context, orgID, id uuid.UUID) (*types.Workflow, error) {
+	w, err := scanWorkflow(r.db.QueryRowContext(ctx, `SELECT `+workflowColumns+workflowFrom+`
+		WHERE w.organization_id = $1 AND w.id = $2`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("workflow %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find workflow: %w", err)
+	}
+	if w.Tasks, err = r.queryTasks(ctx, `t.workflow_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CancelWorkflow cancels an open workflow, leaving its tasks as they are
+func (r *OnboardingRepository) CancelWorkflow(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE onboarding_workflows SET state = 'cancelled', updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'in_progress'
+	`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel workflow: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.FindWorkflow(ctx, orgID, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: only open workflows can be cancelled", ErrInvalidState)
+	}
+	return nil
+}
+
+// CompleteWorkflow completes an open workflow once no required task is
+// left open, reporting whether it did
+func (r *OnboardingRepository) CompleteWorkflow(ctx context.Context, orgID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE onboarding_workflows SET state = 'completed', completed_at = now(), updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'in_progress'
+			AND NOT EXISTS (
+				SELECT 1 FROM onboarding_tasks
+				WHERE workflow_id = $2 AND required AND status IN ('pending', 'failed')
+			)
+	`, orgID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete workflow: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+const taskColumns = `
+	t.id, t.organization_id, t.workflow_id, t.sequence, t.name, COALESCE(t.description, ''), t.task_type,
+	t.assignee_user_id, t.due_date, t.required, t.status, COALESCE(t.provisioning_system, ''),
+	COALESCE(t.equipment_name, ''), t.equipment_assignment_id, COALESCE(t.document_url, ''), COALESCE(t.notes, ''),
+	COALESCE(t.error, ''), t.completed_at, t.completed_by, t.created_at, t.updated_at
+`
+
+func scanTask(row interface{ Scan(...interface{}) error }) (*types.Task, error) {
+	var t types.Task
+	var assigneeUserID, equipmentID, completedBy uuid.NullUUID
+	var dueDate, completedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.OrganizationID, &t.WorkflowID, &t.Sequence, &t.Name, &t.Description, &t.TaskType,
+		&assigneeUserID, &dueDate, &t.Required, &t.Status, &t.ProvisioningSystem, &t.EquipmentName, &equipmentID,
+		&t.DocumentURL, &t.Notes, &t.Error, &completedAt, &completedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.AssigneeUserID = nullUUID(assigneeUserID)
+	t.EquipmentAssignmentID = nullUUID(equipmentID)
+	t.CompletedBy = nullUUID(completedBy)
+	if dueDate.Valid {
+		t.DueDate = &dueDate.Time
+	}
+	if completedAt.Valid {
+		t.CompletedAt = &completedAt.Time
+	}
+	return &t, nil
+}
+
+func (r *OnboardingRepository) queryTasks(ctx context.Context, where string, args ...interface{}) ([]types.Task, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM onboarding_tasks t WHERE `+where+`
+		ORDER BY t.sequence, t.created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []types.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, *t)
+	}
+	return tasks, rows.Err()
+}
+
+// ListTasks returns the tasks of open workflows matching the filter,
+// soonest due first
+func (r *OnboardingRepository) ListTasks(ctx context.Context, filter types.TaskFilter) ([]types.Task, error) {
+	where := []string{"t.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.AssigneeUserID != nil {
+		args = append(args, *filter.AssigneeUserID)
+		where = append(where, fmt.Sprintf("t.assignee_user_id = $%d", len(args)))
+	}
+	if filter.OpenOnly {
+		where = append(where, "t.status IN ('pending', 'failed')")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+taskColumns+`
+		FROM onboarding_tasks t JOIN onboarding_workflows w ON w.id = t.workflow_id AND w.state = 'in_progress'
+		WHERE `+strings.Join(where, " AND ")+
+		fmt.Sprintf(` ORDER BY t.due_date NULLS LAST, t.sequence LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []types.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, *t)
+	}
+	return tasks, rows.Err()
+}
+
+func (r *OnboardingRepository) FindTask(ctx context.Context, orgID, id uuid.UUID) (*types.Task, error) {
+	t, err := scanTask(r.db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM onboarding_tasks t
+		WHERE t.organization_id = $1 AND t.id = $2`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("task %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+	return t, nil
+}
+
+// SaveTask stores the outcome of an open task of an open workflow. issue
+// records equipment handed over by the task; returnEquipment marks the
+// task's equipment returned.
+func (r *OnboardingRepository) SaveTask(ctx context.Context, userID *uuid.UUID, task types.Task, issue *types.EquipmentAssignment, returnEquipment bool) (*types.Task, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status types.TaskStatus
+	var state types.WorkflowState
+	err = tx.QueryRowContext(ctx, `
+		SELECT t.status, w.state
+		FROM onboarding_tasks t JOIN onboarding_workflows w ON w.id = t.workflow_id
+		WHERE t.organization_id = $1 AND t.id = $2
+		FOR UPDATE OF t
+	`, task.OrganizationID, task.ID).Scan(&status, &state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock task: %w", err)
+	}
+	if state != types.WorkflowInProgress {
+		return nil, fmt.Errorf("%w: the workflow is %s", ErrInvalidState, state)
+	}
+	if !status.IsOpen() {
+		return nil, fmt.Errorf("%w: the task is already %s", ErrInvalidState, status)
+	}
+
+	if issue != nil {
+		var id uuid.UUID
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO equipment_assignments (organization_id, employee_id, name, serial_number, asset_id, created_by)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+			RETURNING id
+		`, issue.OrganizationID, issue.EmployeeID, issue.Name, issue.SerialNumber, issue.AssetID, userID).Scan(&id); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return nil, fmt.Errorf("asset %w", ErrNotFound)
+			}
+			return nil, fmt.Errorf("failed to assign equipment: %w", err)
+		}
+		task.EquipmentAssignmentID = &id
+	}
+	if returnEquipment && task.EquipmentAssignmentID != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE equipment_assignments SET returned_at = now() WHERE id = $1 AND returned_at IS NULL
+		`, *task.EquipmentAssignmentID); err != nil {
+			return nil, fmt.Errorf("failed to return equipment: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE onboarding_tasks SET status = $2, document_url = NULLIF($3, ''), notes = NULLIF($4, ''),
+			error = NULLIF($5, ''), equipment_assignment_id = $6, completed_at = $7, completed_by = $8, updated_at = now()
+		WHERE id = $1
+	`, task.ID, string(task.Status), task.DocumentURL, task.Notes, task.Error, task.EquipmentAssignmentID,
+		task.CompletedAt, task.CompletedBy); err != nil {
+		return nil, fmt.Errorf("failed to save task: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit task: %w", err)
+	}
+	return r.FindTask(ctx, task.OrganizationID, task.ID)
+}
+
+// TransferOwnership moves the contacts, open leads and planned activities
+// of fromUserID to toUserID and records the transfer on the workflow
+func (r *OnboardingRepository) TransferOwnership(ctx context.Context, orgID, workflowID, fromUserID, toUserID uuid.UUID, userID *uuid.UUID) (*types.OwnershipTransfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state types.WorkflowState
+	err = tx.QueryRowContext(ctx, `
+		SELECT state FROM onboarding_workflows WHERE organization_id = $1 AND id = $2 FOR UPDATE
+	`, orgID, workflowID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("workflow %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock workflow: %w", err)
+	}
+	if state == types.WorkflowCancelled {
+		return nil, fmt.Errorf("%w: the workflow is cancelled", ErrInvalidState)
+	}
+
+	transfer := types.OwnershipTransfer{ToUserID: toUserID}
+	updates := []struct {
+		count *int
+		query string
+	}{
+		{&transfer.Contacts, `
+			UPDATE contacts SET user_id = $3, updated_by = COALESCE($4, updated_by), updated_at = now()
+			WHERE organization_id = $1 AND user_id = $2 AND deleted_at IS NULL`},
+		{&transfer.Leads, `
+			UPDATE leads SET user_id = $3, updated_by = COALESCE($4, updated_by), updated_at = now(), version = version + 1
+			WHERE organization_id = $1 AND user_id = $2 AND deleted_at IS NULL AND active
+				AND COALESCE(won_status, 'ongoing') = 'ongoing'`},
+		{&transfer.Activities, `
+			UPDATE activities SET assigned_to = $3, updated_by = COALESCE($4, updated_by), updated_at = now()
+			WHERE organization_id = $1 AND COALESCE(assigned_to, user_id) = $2 AND state = 'planned'`},
+	}
+	for _, u := range updates {
+		result, err := tx.ExecContext(ctx, u.query, orgID, fromUserID, toUserID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer ownership: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		*u.count = int(n)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE onboarding_workflows SET ownership_user_id = $2, ownership_contacts = $3, ownership_leads = $4,
+			ownership_activities = $5, ownership_transferred_at = now(), updated_at = now()
+		WHERE id = $1
+		RETURNING ownership_transferred_at
+	`, workflowID, toUserID, transfer.Contacts, transfer.Leads, transfer.Activities).Scan(&transfer.TransferredAt); err != nil {
+		return nil, fmt.Errorf("failed to record ownership transfer: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ownership transfer: %w", err)
+	}
+	return &transfer, nil
+}
+
+const equipmentColumns = `
+	id, organization_id, employee_id, name, COALESCE(serial_number, ''), asset_id, assigned_at, returned_at
+`
+
+func scanEquipment(row interface{ Scan(...interface{}) error }) (*types.EquipmentAssignment, error) {
+	var a types.EquipmentAssignment
+	var assetID uuid.NullUUID
+	var returnedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.OrganizationID, &a.EmployeeID, &a.Name, &a.SerialNumber, &assetID, &a.AssignedAt,
+		&returnedAt); err != nil {
+		return nil, err
+	}
+	a.AssetID = nullUUID(assetID)
+	if returnedAt.Valid {
+		a.ReturnedAt = &returnedAt.Time
+	}
+	return &a, nil
+}
+
+// ListEquipment returns equipment assignments, most recent first
+func (r *OnboardingRepository) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.EquipmentAssignment, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.EmployeeID != nil {
+		args = append(args, *filter.EmployeeID)
+		where = append(where, fmt.Sprintf("employee_id = $%d", len(args)))
+	}
+	if filter.OpenOnly {
+		where = append(where, "returned_at IS NULL")
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+equipmentColumns+` FROM equipment_assignments
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY assigned_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list equipment: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []types.EquipmentAssignment{}
+	for rows.Next() {
+		a, err := scanEquipment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan equipment: %w", err)
+		}
+		assignments = append(assignments, *a)
+	}
+	return assignments, rows.Err()
+}
+
+// ReturnEquipment marks equipment returned outside an offboarding workflow
+func (r *OnboardingRepository) ReturnEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.EquipmentAssignment, error) {
+	a, err := scanEquipment(r.db.QueryRowContext(ctx, `
+		UPDATE equipment_assignments SET returned_at = COALESCE(returned_at, now())
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+equipmentColumns, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("equipment assignment %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to return equipment: %w", err)
+	}
+	return a, nil
+}
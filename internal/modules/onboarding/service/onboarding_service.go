@@ -0,0 +1,720 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPageSize is the page size of workflow and task lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of workflow and task lists
+	MaxPageSize = 500
+	// MaxDueOffsetDays bounds how far from the start date a task can be due
+	MaxDueOffsetDays = 365
+	// EventEmployeeHired starts the onboarding workflow of the employee
+	EventEmployeeHired = "employee.hired"
+	// EventEmployeeTerminated starts the offboarding workflow of the employee
+	EventEmployeeTerminated = "employee.terminated"
+	// EventWorkflowStarted is published when a workflow starts
+	EventWorkflowStarted = "onboarding.workflow_started"
+	// EventWorkflowCompleted is published when a workflow's last required task is finished
+	EventWorkflowCompleted = "onboarding.workflow_completed"
+)
+
+var (
+	// ErrInvalid wraps validation failures of templates, workflows and tasks
+	ErrInvalid = errors.New("invalid request")
+	// ErrNoTemplate is returned when starting an onboarding workflow without
+	// a template when the organization has no active one
+	ErrNoTemplate = fmt.Errorf("%w: no active onboarding template", ErrInvalid)
+)
+
+// AuthService defines the permission check used by the onboarding service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Provisioner grants and revokes employee accounts in one external system.
+// Provisioning tasks naming a system without a provisioner are done by hand.
+type Provisioner interface {
+	Provision(ctx context.Context, request types.ProvisioningRequest) error
+}
+
+// OnboardingService runs onboarding and offboarding checklists: it starts
+// them from HR events or by hand, provisions accounts, tracks equipment
+// and hands a departing employee's CRM records to their successor
+type OnboardingService struct {
+	repo         repository.OnboardingRepo
+	authService  AuthService
+	eventBus     *events.Bus
+	logger       *slog.Logger
+	now          func() time.Time
+	mu           sync.RWMutex
+	provisioners map[string]Provisioner
+}
+
+func NewOnboardingService(repo repository.OnboardingRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *OnboardingService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &OnboardingService{
+		repo:         repo,
+		authService:  authService,
+		eventBus:     eventBus,
+		logger:       logger,
+		now:          time.Now,
+		provisioners: make(map[string]Provisioner),
+	}
+}
+
+// today is the current date at midnight UTC
+func (s *OnboardingService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+func systemKey(system string) string {
+	return strings.ToLower(strings.TrimSpace(system))
+}
+
+// RegisterProvisioner makes provisioning tasks naming the system run
+// against the provisioner
+func (s *OnboardingService) RegisterProvisioner(system string, provisioner Provisioner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provisioners[systemKey(system)] = provisioner
+}
+
+func (s *OnboardingService) provisioner(system string) Provisioner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.provisioners[systemKey(system)]
+}
+
+// ListTemplates returns the templates of an organization, optionally of one kind
+func (s *OnboardingService) ListTemplates(ctx context.Context, orgID uuid.UUID, kind types.Kind) ([]types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if kind != "" && !kind.IsValid() {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalid, kind)
+	}
+	return s.repo.ListTemplates(ctx, orgID, kind)
+}
+
+// GetTemplate returns a template with its tasks
+func (s *OnboardingService) GetTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindTemplate(ctx, orgID, id)
+}
+
+// CreateTemplate validates and creates a template
+func (s *OnboardingService) CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateTemplate(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateTemplate(ctx, orgID, userID, request)
+}
+
+// UpdateTemplate validates and replaces a template and its tasks
+func (s *OnboardingService) UpdateTemplate(ctx context.Context, orgID, userID, id uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateTemplate(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateTemplate(ctx, orgID, userID, id, request)
+}
+
+// DeleteTemplate removes a template
+func (s *OnboardingService) DeleteTemplate(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteTemplate(ctx, orgID, id)
+}
+
+// validateTemplate normalizes a template request and checks its kind and tasks
+func validateTemplate(request *types.TemplateRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if !request.Kind.IsValid() {
+		return fmt.Errorf("%w: kind must be onboarding or offboarding", ErrInvalid)
+	}
+	if len(request.Tasks) == 0 {
+		return fmt.Errorf("%w: a template needs at least one task", ErrInvalid)
+	}
+	for i := range request.Tasks {
+		t := &request.Tasks[i]
+		t.Name = strings.TrimSpace(t.Name)
+		t.Description = strings.TrimSpace(t.Description)
+		t.ProvisioningSystem = systemKey(t.ProvisioningSystem)
+		t.EquipmentName = strings.TrimSpace(t.EquipmentName)
+		if t.Name == "" {
+			return fmt.Errorf("%w: task %d needs a name", ErrInvalid, i+1)
+		}
+		if t.TaskType == "" {
+			t.TaskType = types.TaskTypeTask
+		}
+		if !t.TaskType.IsValid() {
+			return fmt.Errorf("%w: task %q has unknown type %q", ErrInvalid, t.Name, t.TaskType)
+		}
+		if t.Assignee == "" {
+			t.Assignee = types.AssigneeManager
+		}
+		if !t.Assignee.IsValid() {
+			return fmt.Errorf("%w: task %q has unknown assignee %q", ErrInvalid, t.Name, t.Assignee)
+		}
+		if t.Assignee == types.AssigneeUser && t.AssigneeUserID == nil {
+			return fmt.Errorf("%w: task %q is assigned to a user but names none", ErrInvalid, t.Name)
+		}
+		if t.Assignee != types.AssigneeUser {
+			t.AssigneeUserID = nil
+		}
+		if t.DueOffsetDays < -MaxDueOffsetDays || t.DueOffsetDays > MaxDueOffsetDays {
+			return fmt.Errorf("%w: task %q must be due within %d days of the start date", ErrInvalid, t.Name, MaxDueOffsetDays)
+		}
+		if t.TaskType == types.TaskTypeProvisioning && t.ProvisioningSystem == "" {
+			return fmt.Errorf("%w: provisioning task %q needs a provisioning_system", ErrInvalid, t.Name)
+		}
+		if t.TaskType != types.TaskTypeProvisioning {
+			t.ProvisioningSystem = ""
+		}
+		if t.TaskType != types.TaskTypeEquipment {
+			t.EquipmentName = ""
+		}
+		if t.Sequence == 0 {
+			t.Sequence = (i + 1) * 10
+		}
+	}
+	return nil
+}
+
+// StartWorkflow starts a workflow for an employee by hand
+func (s *OnboardingService) StartWorkflow(ctx context.Context, orgID, userID uuid.UUID, request types.StartRequest) (*types.Workflow, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.start(ctx, orgID, &userID, request)
+}
+
+// HandleEmployeeEvent starts the workflow an HR event calls for. It runs
+// without a user, so it skips the permission check. Organizations without
+// an onboarding template get no onboarding workflow.
+func (s *OnboardingService) HandleEmployeeEvent(ctx context.Context, kind types.Kind, event types.EmployeeEvent) (*types.Workflow, error) {
+	if event.OrganizationID == uuid.Nil || event.EmployeeID == uuid.Nil {
+		return nil, fmt.Errorf("%w: organization_id and employee_id are required", ErrInvalid)
+	}
+	workflow, err := s.start(ctx, event.OrganizationID, nil, types.StartRequest{
+		EmployeeID: event.EmployeeID,
+		Kind:       kind,
+		StartDate:  event.Date,
+		TransferTo: event.TransferTo,
+	})
+	if errors.Is(err, ErrNoTemplate) {
+		s.logger.Info("No onboarding template, skipping onboarding", "employee_id", event.EmployeeID)
+		return nil, nil
+	}
+	return workflow, err
+}
+
+// start creates the workflow with its template's tasks, a return task for
+// each piece of equipment still assigned on offboarding, then runs the
+// provisioning tasks and, on offboarding, transfers CRM ownership
+func (s *OnboardingService) start(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, request types.StartRequest) (*types.Workflow, error) {
+	if !request.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: kind must be onboarding or offboarding", ErrInvalid)
+	}
+	if request.EmployeeID == uuid.Nil {
+		return nil, fmt.Errorf("%w: employee_id is required", ErrInvalid)
+	}
+	employee, err := s.repo.FindEmployee(ctx, orgID, request.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var template *types.Template
+	if request.TemplateID != nil {
+		if template, err = s.repo.FindTemplate(ctx, orgID, *request.TemplateID); err != nil {
+			return nil, err
+		}
+		if template.Kind != request.Kind {
+			return nil, fmt.Errorf("%w: template %q is an %s template", ErrInvalid, template.Name, template.Kind)
+		}
+		if !template.Active {
+			return nil, fmt.Errorf("%w: template %q is inactive", ErrInvalid, template.Name)
+		}
+	} else {
+		template, err = s.repo.FindDefaultTemplate(ctx, orgID, request.Kind, employee.DepartmentID)
+		switch {
+		case err == nil:
+		case !errors.Is(err, repository.ErrNotFound):
+			return nil, err
+		case request.Kind == types.KindOnboarding:
+			return nil, ErrNoTemplate
+		}
+		// Offboarding still takes equipment back and hands over CRM records
+		// without a template
+	}
+
+	startDate := s.today()
+	switch {
+	case request.StartDate != nil:
+		startDate = *request.StartDate
+	case request.Kind == types.KindOnboarding && employee.DateHired != nil:
+		startDate = *employee.DateHired
+	case request.Kind == types.KindOffboarding && employee.DateTerminated != nil:
+		startDate = *employee.DateTerminated
+	}
+	startDate = startDate.UTC().Truncate(24 * time.Hour)
+
+	workflow := types.Workflow{
+		OrganizationID: orgID,
+		EmployeeID:     employee.ID,
+		Kind:           request.Kind,
+		StartDate:      startDate,
+	}
+	if template != nil {
+		workflow.TemplateID = &template.ID
+		workflow.Tasks = buildTasks(*template, *employee, startDate)
+	}
+	if request.Kind == types.KindOffboarding {
+		equipment, err := s.repo.ListEquipment(ctx, types.EquipmentFilter{
+			OrganizationID: orgID,
+			EmployeeID:     &employee.ID,
+			OpenOnly:       true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		workflow.Tasks = append(workflow.Tasks, returnTasks(equipment, *employee, startDate, len(workflow.Tasks))...)
+	}
+
+	created, err := s.repo.CreateWorkflow(ctx, userID, workflow)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Started workflow", "kind", created.Kind, "employee_id", employee.ID, "workflow_id", created.ID)
+
+	for _, task := range created.Tasks {
+		if task.TaskType == types.TaskTypeProvisioning && task.Status.IsOpen() {
+			if _, err := s.provision(ctx, created, employee, task, userID); err != nil && !errors.Is(err, ErrInvalid) {
+				s.logger.Error("Failed to save provisioning result", "task_id", task.ID, "error", err)
+			}
+		}
+	}
+
+	if request.Kind == types.KindOffboarding && employee.UserID != nil {
+		to := request.TransferTo
+		if to == nil {
+			to = employee.ManagerUserID
+		}
+		switch {
+		case to == nil:
+			s.logger.Warn("No successor for CRM records of departing employee", "employee_id", employee.ID)
+		case *to != *employee.UserID:
+			// A failed transfer can be redone from the workflow, so it does
+			// not undo the workflow
+			if _, err := s.repo.TransferOwnership(ctx, orgID, created.ID, *employee.UserID, *to, userID); err != nil {
+				s.logger.Error("Failed to transfer CRM ownership", "workflow_id", created.ID, "error", err)
+			}
+		}
+	}
+
+	s.publishEvent(ctx, EventWorkflowStarted, created)
+	s.completeIfDone(ctx, orgID, created.ID)
+
+	result, err := s.repo.FindWorkflow(ctx, orgID, created.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.Progress = progress(result.Tasks, s.today())
+	return result, nil
+}
+
+// buildTasks copies a template's tasks, resolving their assignees and due dates
+func buildTasks(template types.Template, employee types.Employee, startDate time.Time) []types.Task {
+	tasks := make([]types.Task, 0, len(template.Tasks))
+	for _, t := range template.Tasks {
+		due := startDate.AddDate(0, 0, t.DueOffsetDays)
+		task := types.Task{
+			OrganizationID:     employee.OrganizationID,
+			Sequence:           t.Sequence,
+			Name:               t.Name,
+			Description:        t.Description,
+			TaskType:           t.TaskType,
+			DueDate:            &due,
+			Required:           t.Required,
+			Status:             types.TaskPending,
+			ProvisioningSystem: t.ProvisioningSystem,
+			EquipmentName:      t.EquipmentName,
+		}
+		switch t.Assignee {
+		case types.AssigneeEmployee:
+			task.AssigneeUserID = employee.UserID
+		case types.AssigneeManager:
+			task.AssigneeUserID = employee.ManagerUserID
+		case types.AssigneeUser:
+			task.AssigneeUserID = t.AssigneeUserID
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// returnTasks adds a required task taking back each piece of equipment,
+// due on the last day and sequenced after the template's tasks
+func returnTasks(equipment []types.EquipmentAssignment, employee types.Employee, startDate time.Time, after int) []types.Task {
+	tasks := make([]types.Task, 0, len(equipment))
+	for i, e := range equipment {
+		due := startDate
+		name := "Return " + e.Name
+		if e.SerialNumber != "" {
+			name += " (" + e.SerialNumber + ")"
+		}
+		id := e.ID
+		tasks = append(tasks, types.Task{
+			OrganizationID:        employee.OrganizationID,
+			Sequence:              (after + i + 1) * 10,
+			Name:                  name,
+			TaskType:              types.TaskTypeEquipment,
+			AssigneeUserID:        employee.ManagerUserID,
+			DueDate:               &due,
+			Required:              true,
+			Status:                types.TaskPending,
+			EquipmentName:         e.Name,
+			EquipmentAssignmentID: &id,
+		})
+	}
+	return tasks
+}
+
+// provision runs a provisioning task against its system's provisioner,
+// marking it done or failed. Without a provisioner the task is left for
+// someone to do by hand.
+func (s *OnboardingService) provision(ctx context.Context, workflow *types.Workflow, employee *types.Employee, task types.Task, userID *uuid.UUID) (*types.Task, error) {
+	provisioner := s.provisioner(task.ProvisioningSystem)
+	if provisioner == nil {
+		return nil, fmt.Errorf("%w: no provisioner is registered for %q", ErrInvalid, task.ProvisioningSystem)
+	}
+	action := types.ProvisioningGrant
+	if workflow.Kind == types.KindOffboarding {
+		action = types.ProvisioningRevoke
+	}
+
+	err := provisioner.Provision(ctx, types.ProvisioningRequest{
+		OrganizationID: workflow.OrganizationID,
+		EmployeeID:     employee.ID,
+		EmployeeName:   employee.Name,
+		UserID:         employee.UserID,
+		TaskID:         task.ID,
+		System:         task.ProvisioningSystem,
+		Action:         action,
+	})
+	if err != nil {
+		s.logger.Warn("Provisioning failed", "system", task.ProvisioningSystem, "task_id", task.ID, "error", err)
+		task.Status = types.TaskFailed
+		task.Error = err.Error()
+	} else {
+		now := s.now()
+		task.Status = types.TaskDone
+		task.Error = ""
+		task.CompletedAt = &now
+		task.CompletedBy = userID
+	}
+	return s.repo.SaveTask(ctx, userID, task, nil, false)
+}
+
+// RetryProvisioning runs an open provisioning task against its provisioner again
+func (s *OnboardingService) RetryProvisioning(ctx context.Context, orgID, userID, taskID uuid.UUID) (*types.Task, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding_tasks:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	task, workflow, err := s.openTask(ctx, orgID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.TaskType != types.TaskTypeProvisioning {
+		return nil, fmt.Errorf("%w: only provisioning tasks can be provisioned", ErrInvalid)
+	}
+	employee, err := s.repo.FindEmployee(ctx, orgID, workflow.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.provision(ctx, workflow, employee, *task, &userID)
+	if err != nil {
+		return nil, err
+	}
+	s.completeIfDone(ctx, orgID, workflow.ID)
+	return saved, nil
+}
+
+// CompleteTask completes or skips a task and completes its workflow once
+// no required task is left
+func (s *OnboardingService) CompleteTask(ctx context.Context, orgID, userID, taskID uuid.UUID, completion types.TaskCompletion) (*types.Task, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding_tasks:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	task, workflow, err := s.openTask(ctx, orgID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	completion.DocumentURL = strings.TrimSpace(completion.DocumentURL)
+	completion.Notes = strings.TrimSpace(completion.Notes)
+	completion.SerialNumber = strings.TrimSpace(completion.SerialNumber)
+
+	var issue *types.EquipmentAssignment
+	returnEquipment := false
+	if completion.Skip {
+		if task.Required {
+			return nil, fmt.Errorf("%w: required tasks cannot be skipped", ErrInvalid)
+		}
+		task.Status = types.TaskSkipped
+	} else {
+		switch task.TaskType {
+		case types.TaskTypeDocument:
+			if completion.DocumentURL == "" {
+				return nil, fmt.Errorf("%w: document_url is required to complete a document task", ErrInvalid)
+			}
+			task.DocumentURL = completion.DocumentURL
+		case types.TaskTypeEquipment:
+			if workflow.Kind == types.KindOffboarding {
+				returnEquipment = true
+			} else if task.EquipmentAssignmentID == nil {
+				name := task.EquipmentName
+				if name == "" {
+					name = task.Name
+				}
+				issue = &types.EquipmentAssignment{
+					OrganizationID: orgID,
+					EmployeeID:     workflow.EmployeeID,
+					Name:           name,
+					SerialNumber:   completion.SerialNumber,
+					AssetID:        completion.AssetID,
+				}
+			}
+		}
+		task.Status = types.TaskDone
+		task.Error = ""
+	}
+	now := s.now()
+	task.Notes = completion.Notes
+	task.CompletedAt = &now
+	task.CompletedBy = &userID
+
+	saved, err := s.repo.SaveTask(ctx, &userID, *task, issue, returnEquipment)
+	if err != nil {
+		return nil, err
+	}
+	s.completeIfDone(ctx, orgID, workflow.ID)
+	return saved, nil
+}
+
+// openTask returns an open task of an open workflow, with the workflow
+func (s *OnboardingService) openTask(ctx context.Context, orgID, taskID uuid.UUID) (*types.Task, *types.Workflow, error) {
+	task, err := s.repo.FindTask(ctx, orgID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	workflow, err := s.repo.FindWorkflow(ctx, orgID, task.WorkflowID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if workflow.State != types.WorkflowInProgress {
+		return nil, nil, fmt.Errorf("%w: the workflow is %s", repository.ErrInvalidState, workflow.State)
+	}
+	if !task.Status.IsOpen() {
+		return nil, nil, fmt.Errorf("%w: the task is already %s", repository.ErrInvalidState, task.Status)
+	}
+	return task, workflow, nil
+}
+
+// completeIfDone completes the workflow when no required task is left and
+// announces it
+func (s *OnboardingService) completeIfDone(ctx context.Context, orgID, workflowID uuid.UUID) {
+	completed, err := s.repo.CompleteWorkflow(ctx, orgID, workflowID)
+	if err != nil {
+		s.logger.Error("Failed to complete workflow", "workflow_id", workflowID, "error", err)
+		return
+	}
+	if !completed {
+		return
+	}
+	workflow, err := s.repo.FindWorkflow(ctx, orgID, workflowID)
+	if err != nil {
+		s.logger.Error("Failed to load completed workflow", "workflow_id", workflowID, "error", err)
+		return
+	}
+	workflow.Progress = progress(workflow.Tasks, s.today())
+	s.publishEvent(ctx, EventWorkflowCompleted, workflow)
+}
+
+// publishEvent publishes an event to the event bus if available
+func (s *OnboardingService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish onboarding event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListWorkflows returns a page of workflows with their progress
+func (s *OnboardingService) ListWorkflows(ctx context.Context, filter types.WorkflowFilter) ([]types.Workflow, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Kind != "" && !filter.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalid, filter.Kind)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+
+	workflows, err := s.repo.ListWorkflows(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	today := s.today()
+	for i := range workflows {
+		workflows[i].Progress = progress(workflows[i].Tasks, today)
+		workflows[i].Tasks = nil
+	}
+	return workflows, nil
+}
+
+// GetWorkflow returns a workflow with its tasks and progress
+func (s *OnboardingService) GetWorkflow(ctx context.Context, orgID, id uuid.UUID) (*types.Workflow, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	workflow, err := s.repo.FindWorkflow(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	workflow.Progress = progress(workflow.Tasks, s.today())
+	return workflow, nil
+}
+
+// CancelWorkflow cancels an open workflow
+func (s *OnboardingService) CancelWorkflow(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.CancelWorkflow(ctx, orgID, id)
+}
+
+// TransferOwnership hands a departing employee's contacts, open leads and
+// planned activities to another user, for when the automatic transfer had
+// no successor or picked the wrong one
+func (s *OnboardingService) TransferOwnership(ctx context.Context, orgID, userID, workflowID uuid.UUID, request types.TransferRequest) (*types.OwnershipTransfer, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.ToUserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: to_user_id is required", ErrInvalid)
+	}
+	workflow, err := s.repo.FindWorkflow(ctx, orgID, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if workflow.Kind != types.KindOffboarding {
+		return nil, fmt.Errorf("%w: ownership is only transferred on offboarding", ErrInvalid)
+	}
+	employee, err := s.repo.FindEmployee(ctx, orgID, workflow.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if employee.UserID == nil {
+		return nil, fmt.Errorf("%w: the employee has no user owning CRM records", ErrInvalid)
+	}
+	if *employee.UserID == request.ToUserID {
+		return nil, fmt.Errorf("%w: cannot transfer ownership to the departing employee", ErrInvalid)
+	}
+	return s.repo.TransferOwnership(ctx, orgID, workflowID, *employee.UserID, request.ToUserID, &userID)
+}
+
+// ListTasks returns a page of tasks of open workflows, soonest due first
+func (s *OnboardingService) ListTasks(ctx context.Context, filter types.TaskFilter) ([]types.Task, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding_tasks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListTasks(ctx, filter)
+}
+
+// ListEquipment returns equipment assignments
+func (s *OnboardingService) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.EquipmentAssignment, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListEquipment(ctx, filter)
+}
+
+// ReturnEquipment marks equipment returned
+func (s *OnboardingService) ReturnEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.EquipmentAssignment, error) {
+	if err := s.authService.CheckPermission(ctx, "onboarding:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ReturnEquipment(ctx, orgID, id)
+}
+
+// progress counts tasks by status; tasks still open past their due date
+// are overdue
+func progress(tasks []types.Task, today time.Time) types.Progress {
+	p := types.Progress{Total: len(tasks)}
+	for _, t := range tasks {
+		switch t.Status {
+		case types.TaskDone:
+			p.Done++
+		case types.TaskSkipped:
+			p.Skipped++
+		case types.TaskPending:
+			p.Pending++
+		case types.TaskFailed:
+			p.Failed++
+		}
+		if t.Status.IsOpen() && t.DueDate != nil && t.DueDate.Before(today) {
+			p.Overdue++
+		}
+	}
+	p.Percent = 100
+	if p.Total > 0 {
+		p.Percent = math.Round(float64(p.Done+p.Skipped)/float64(p.Total)*1000) / 10
+	}
+	return p
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/onboarding/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transferCall struct {
+	from, to uuid.UUID
+}
+
+type fakeOnboardingRepo struct {
+	templates []types.Template
+	employees []types.Employee
+	workflows []types.Workflow
+	tasks     []types.Task
+	equipment []types.EquipmentAssignment
+	transfers []transferCall
+	issued    []types.EquipmentAssignment
+	returned  []uuid.UUID
+}
+
+func (f *fakeOnboardingRepo) ListTemplates(ctx context.Context, orgID uuid.UUID, kind types.Kind) ([]types.Template, error) {
+	return f.templates, nil
+}
+
+func (f *fakeOnboardingRepo) FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.Template, error) {
+	for i := range f.templates {
+		if f.templates[i].ID == id {
+			return &f.templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("template %w", repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) FindDefaultTemplate(ctx context.Context, orgID uuid.UUID, kind types.Kind, departmentID *uuid.UUID) (*types.Template, error) {
+	for i := range f.templates {
+		if f.templates[i].Kind == kind && f.templates[i].Active {
+			return &f.templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s template %w", kind, repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	return nil, nil
+}
+
+func (f *fakeOnboardingRepo) UpdateTemplate(ctx context.Context, orgID, userID, id uuid.UUID, request types.TemplateRequest) (*types.Template, error) {
+	return nil, nil
+}
+
+func (f *fakeOnboardingRepo) DeleteTemplate(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeOnboardingRepo) FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error) {
+	for i := range f.employees {
+		if f.employees[i].ID == id {
+			e := f.employees[i]
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("employee %w", repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) CreateWorkflow(ctx context.Context, userID *uuid.UUID, workflow types.Workflow) (*types.Workflow, error) {
+	for _, w := range f.workflows {
+		if w.EmployeeID == workflow.EmployeeID && w.Kind == workflow.Kind && w.State == types.WorkflowInProgress {
+			return nil, fmt.Errorf("open %s workflow for this employee %w", workflow.Kind, repository.ErrDuplicate)
+		}
+	}
+	workflow.ID = uuid.New()
+	workflow.State = types.WorkflowInProgress
+	for _, t := range workflow.Tasks {
+		t.ID = uuid.New()
+		t.WorkflowID = workflow.ID
+		f.tasks = append(f.tasks, t)
+	}
+	workflow.Tasks = nil
+	f.workflows = append(f.workflows, workflow)
+	return f.FindWorkflow(ctx, workflow.OrganizationID, workflow.ID)
+}
+
+func (f *fakeOnboardingRepo) ListWorkflows(ctx context.Context, filter types.WorkflowFilter) ([]types.Workflow, error) {
+	var workflows []types.Workflow
+	for _, w := range f.workflows {
+		found, _ := f.FindWorkflow(ctx, w.OrganizationID, w.ID)
+		workflows = append(workflows, *found)
+	}
+	return workflows, nil
+}
+
+func (f *fakeOnboardingRepo) FindWorkflow(ctx context.Context, orgID, id uuid.UUID) (*types.Workflow, error) {
+	for _, w := range f.workflows {
+		if w.ID == id {
+			w.Tasks = nil
+			for _, t := range f.tasks {
+				if t.WorkflowID == id {
+					w.Tasks = append(w.Tasks, t)
+				}
+			}
+			return &w, nil
+		}
+	}
+	return nil, fmt.Errorf("workflow %w", repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) CancelWorkflow(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeOnboardingRepo) CompleteWorkflow(ctx context.Context, orgID, id uuid.UUID) (bool, error) {
+	for _, t := range f.tasks {
+		if t.WorkflowID == id && t.Required && t.Status.IsOpen() {
+			return false, nil
+		}
+	}
+	for i := range f.workflows {
+		if f.workflows[i].ID == id && f.workflows[i].State == types.WorkflowInProgress {
+			f.workflows[i].State = types.WorkflowCompleted
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeOnboardingRepo) ListTasks(ctx context.Context, filter types.TaskFilter) ([]types.Task, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeOnboardingRepo) FindTask(ctx context.Context, orgID, id uuid.UUID) (*types.Task, error) {
+	for i := range f.tasks {
+		if f.tasks[i].ID == id {
+			t := f.tasks[i]
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("task %w", repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) SaveTask(ctx context.Context, userID *uuid.UUID, task types.Task, issue *types.EquipmentAssignment, returnEquipment bool) (*types.Task, error) {
+	if issue != nil {
+		issue.ID = uuid.New()
+		f.issued = append(f.issued, *issue)
+		task.EquipmentAssignmentID = &issue.ID
+	}
+	if returnEquipment && task.EquipmentAssignmentID != nil {
+		f.returned = append(f.returned, *task.EquipmentAssignmentID)
+	}
+	for i := range f.tasks {
+		if f.tasks[i].ID == task.ID {
+			f.tasks[i] = task
+			return &task, nil
+		}
+	}
+	return nil, fmt.Errorf("task %w", repository.ErrNotFound)
+}
+
+func (f *fakeOnboardingRepo) TransferOwnership(ctx context.Context, orgID, workflowID, fromUserID, toUserID uuid.UUID, userID *uuid.UUID) (*types.OwnershipTransfer, error) {
+	f.transfers = append(f.transfers, transferCall{from: fromUserID, to: toUserID})
+	return &types.OwnershipTransfer{ToUserID: toUserID, Leads: 3}, nil
+}
+
+func (f *fakeOnboardingRepo) ListEquipment(ctx context.Context, filter types.EquipmentFilter) ([]types.EquipmentAssignment, error) {
+	return f.equipment, nil
+}
+
+func (f *fakeOnboardingRepo) ReturnEquipment(ctx context.Context, orgID, id uuid.UUID) (*types.EquipmentAssignment, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type fakeProvisioner struct {
+	err      error
+	requests []types.ProvisioningRequest
+}
+
+func (p *fakeProvisioner) Provision(ctx context.Context, request types.ProvisioningRequest) error {
+	p.requests = append(p.requests, request)
+	return p.err
+}
+
+func newTestService(repo *fakeOnboardingRepo) *OnboardingService {
+	svc := NewOnboardingService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testEmployee() types.Employee {
+	userID, managerID := uuid.New(), uuid.New()
+	hired, terminated := day(time.April, 7), day(time.June, 30)
+	return types.Employee{
+		ID: uuid.New(), OrganizationID: uuid.New(), Name: "Ada Lovelace", UserID: &userID, ManagerUserID: &managerID,
+		DateHired: &hired, DateTerminated: &terminated,
+	}
+}
+
+func onboardingTemplate() types.Template {
+	itUser := uuid.New()
+	return types.Template{
+		ID: uuid.New(), Name: "Default onboarding", Kind: types.KindOnboarding, Active: true,
+		Tasks: []types.TemplateTask{
+			{Sequence: 10, Name: "Sign contract", TaskType: types.TaskTypeDocument, Assignee: types.AssigneeEmployee, DueOffsetDays: -3, Required: true},
+			{Sequence: 20, Name: "Email account", TaskType: types.TaskTypeProvisioning, Assignee: types.AssigneeUser, AssigneeUserID: &itUser, Required: true, ProvisioningSystem: "email"},
+			{Sequence: 30, Name: "Laptop", TaskType: types.TaskTypeEquipment, Assignee: types.AssigneeManager, Required: true, EquipmentName: "Laptop"},
+			{Sequence: 40, Name: "Team lunch", TaskType: types.TaskTypeTask, Assignee: types.AssigneeManager, DueOffsetDays: 5},
+		},
+	}
+}
+
+func taskNamed(t *testing.T, w *types.Workflow, name string) types.Task {
+	t.Helper()
+	for _, task := range w.Tasks {
+		if task.Name == name {
+			return task
+		}
+	}
+	t.Fatalf("no task %q", name)
+	return types.Task{}
+}
+
+func TestStartOnboardingBuildsTasksAndProvisions(t *testing.T) {
+	employee := testEmployee()
+	template := onboardingTemplate()
+	repo := &fakeOnboardingRepo{templates: []types.Template{template}, employees: []types.Employee{employee}}
+	svc := newTestService(repo)
+	email := &fakeProvisioner{}
+	svc.RegisterProvisioner("Email", email)
+
+	workflow, err := svc.HandleEmployeeEvent(context.Background(), types.KindOnboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: employee.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, workflow.Tasks, 4)
+	assert.Equal(t, day(time.April, 7), workflow.StartDate, "starts on the hire date")
+	assert.Equal(t, &template.ID, workflow.TemplateID)
+
+	contract := taskNamed(t, workflow, "Sign contract")
+	assert.Equal(t, employee.UserID, contract.AssigneeUserID)
+	assert.Equal(t, day(time.April, 4), *contract.DueDate)
+	lunch := taskNamed(t, workflow, "Team lunch")
+	assert.Equal(t, employee.ManagerUserID, lunch.AssigneeUserID)
+	assert.Equal(t, day(time.April, 12), *lunch.DueDate)
+	assert.False(t, lunch.Required)
+
+	account := taskNamed(t, workflow, "Email account")
+	assert.Equal(t, template.Tasks[1].AssigneeUserID, account.AssigneeUserID)
+	assert.Equal(t, types.TaskDone, account.Status)
+	require.Len(t, email.requests, 1)
+	assert.Equal(t, types.ProvisioningGrant, email.requests[0].Action)
+	assert.Equal(t, employee.UserID, email.requests[0].UserID)
+
+	assert.Equal(t, types.Progress{Total: 4, Done: 1, Pending: 3, Percent: 25}, workflow.Progress)
+	assert.Empty(t, repo.transfers, "onboarding transfers no ownership")
+
+	_, err = svc.HandleEmployeeEvent(context.Background(), types.KindOnboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: employee.ID,
+	})
+	assert.ErrorIs(t, err, repository.ErrDuplicate)
+}
+
+func TestHandleEmployeeEventWithoutOnboardingTemplate(t *testing.T) {
+	employee := testEmployee()
+	repo := &fakeOnboardingRepo{employees: []types.Employee{employee}}
+	svc := newTestService(repo)
+
+	workflow, err := svc.HandleEmployeeEvent(context.Background(), types.KindOnboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: employee.ID,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, workflow)
+	assert.Empty(t, repo.workflows)
+
+	_, err = svc.StartWorkflow(context.Background(), employee.OrganizationID, uuid.New(), types.StartRequest{
+		EmployeeID: employee.ID, Kind: types.KindOnboarding,
+	})
+	assert.ErrorIs(t, err, ErrNoTemplate)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = svc.HandleEmployeeEvent(context.Background(), types.KindOnboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: uuid.New(),
+	})
+	assert.ErrorIs(t, err, repository.ErrNotFound, "unknown employees are still reported")
+}
+
+func TestOffboardingReturnsEquipmentAndTransfersOwnership(t *testing.T) {
+	employee := testEmployee()
+	laptop := types.EquipmentAssignment{ID: uuid.New(), EmployeeID: employee.ID, Name: "Laptop", SerialNumber: "SN-1"}
+	repo := &fakeOnboardingRepo{employees: []types.Employee{employee}, equipment: []types.EquipmentAssignment{laptop}}
+	svc := newTestService(repo)
+
+	workflow, err := svc.HandleEmployeeEvent(context.Background(), types.KindOffboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: employee.ID,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, workflow.TemplateID, "offboarding runs without a template")
+	assert.Equal(t, day(time.June, 30), workflow.StartDate, "starts on the last day")
+	require.Len(t, workflow.Tasks, 1)
+	task := workflow.Tasks[0]
+	assert.Equal(t, "Return Laptop (SN-1)", task.Name)
+	assert.Equal(t, &laptop.ID, task.EquipmentAssignmentID)
+	assert.True(t, task.Required)
+
+	require.Len(t, repo.transfers, 1)
+	assert.Equal(t, transferCall{from: *employee.UserID, to: *employee.ManagerUserID}, repo.transfers[0],
+		"CRM records go to the manager by default")
+
+	_, err = svc.CompleteTask(context.Background(), employee.OrganizationID, uuid.New(), task.ID, types.TaskCompletion{})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{laptop.ID}, repo.returned)
+	assert.Equal(t, types.WorkflowCompleted, repo.workflows[0].State)
+
+	_, err = svc.CompleteTask(context.Background(), employee.OrganizationID, uuid.New(), task.ID, types.TaskCompletion{})
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+}
+
+func TestOffboardingTransferTarget(t *testing.T) {
+	employee := testEmployee()
+	successor := uuid.New()
+	repo := &fakeOnboardingRepo{employees: []types.Employee{employee}}
+	svc := newTestService(repo)
+
+	workflow, err := svc.HandleEmployeeEvent(context.Background(), types.KindOffboarding, types.EmployeeEvent{
+		OrganizationID: employee.OrganizationID, EmployeeID: employee.ID, TransferTo: &successor,
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.transfers, 1)
+	assert.Equal(t, successor, repo.transfers[0].to)
+	assert.Equal(t, types.WorkflowCompleted, repo.workflows[0].State, "nothing left to do")
+
+	_, err = svc.TransferOwnership(context.Background(), employee.OrganizationID, uuid.New(), workflow.ID,
+		types.TransferRequest{ToUserID: *employee.UserID})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	other := uuid.New()
+	transfer, err := svc.TransferOwnership(context.Background(), employee.OrganizationID, uuid.New(), workflow.ID,
+		types.TransferRequest{ToUserID: other})
+	require.NoError(t, err)
+	assert.Equal(t, other, transfer.ToUserID)
+
+	// Without a manager or an explicit successor nothing moves
+	orphan := testEmployee()
+	orphan.ManagerUserID = nil
+	repo.employees = append(repo.employees, orphan)
+	_, err = svc.HandleEmployeeEvent(context.Background(), types.KindOffboarding, types.EmployeeEvent{
+		OrganizationID: orphan.OrganizationID, EmployeeID: orphan.ID,
+	})
+	require.NoError(t, err)
+	assert.Len(t, repo.transfers, 2)
+}
+
+func TestProvisioningFailureAndRetry(t *testing.T) {
+	employee := testEmployee()
+	template := types.Template{
+		ID: uuid.New(), Name: "Default offboarding", Kind: types.KindOffboarding, Active: true,
+		Tasks: []types.TemplateTask{
+			{Sequence: 10, Name: "Revoke VPN", TaskType: types.TaskTypeProvisioning, Assignee: types.AssigneeManager, Required: true, ProvisioningSystem: "vpn"},
+			{Sequence: 20, Name: "Revoke badge", TaskType: types.TaskTypeProvisioning, Assignee: types.AssigneeManager, Required: true, ProvisioningSystem: "badge"},
+		},
+	}
+	repo := &fakeOnboardingRepo{templates: []types.Template{template}, employees: []types.Employee{employee}}
+	svc := newTestService(repo)
+	vpn := &fakeProvisioner{err: errors.New("vpn unreachable")}
+	svc.RegisterProvisioner("vpn", vpn)
+
+	workflow, err := svc.StartWorkflow(context.Background(), employee.OrganizationID, uuid.New(), types.StartRequest{
+		EmployeeID: employee.ID, Kind: types.KindOffboarding,
+	})
+	require.NoError(t, err)
+	revokeVPN := taskNamed(t, workflow, "Revoke VPN")
+	assert.Equal(t, types.TaskFailed, revokeVPN.Status)
+	assert.Equal(t, "vpn unreachable", revokeVPN.Error)
+	assert.Equal(t, types.ProvisioningRevoke, vpn.requests[0].Action)
+	badge := taskNamed(t, workflow, "Revoke badge")
+	assert.Equal(t, types.TaskPending, badge.Status, "no provisioner leaves the task for someone to do")
+	assert.Equal(t, 1, workflow.Progress.Failed)
+
+	_, err = svc.RetryProvisioning(context.Background(), employee.OrganizationID, uuid.New(), badge.ID)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	vpn.err = nil
+	retried, err := svc.RetryProvisioning(context.Background(), employee.OrganizationID, uuid.New(), revokeVPN.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.TaskDone, retried.Status)
+	assert.Empty(t, retried.Error)
+	assert.Equal(t, types.WorkflowInProgress, repo.workflows[0].State)
+
+	_, err = svc.CompleteTask(context.Background(), employee.OrganizationID, uuid.New(), badge.ID, types.TaskCompletion{})
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowCompleted, repo.workflows[0].State)
+}
+
+func TestCompleteTaskRules(t *testing.T) {
+	employee := testEmployee()
+	repo := &fakeOnboardingRepo{templates: []types.Template{onboardingTemplate()}, employees: []types.Employee{employee}}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	workflow, err := svc.StartWorkflow(ctx, employee.OrganizationID, uuid.New(), types.StartRequest{
+		EmployeeID: employee.ID, Kind: types.KindOnboarding,
+	})
+	require.NoError(t, err)
+
+	contract := taskNamed(t, workflow, "Sign contract")
+	_, err = svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), contract.ID, types.TaskCompletion{Skip: true})
+	assert.ErrorIs(t, err, ErrInvalid, "required tasks cannot be skipped")
+	_, err = svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), contract.ID, types.TaskCompletion{})
+	assert.ErrorIs(t, err, ErrInvalid, "document tasks need a document")
+	done, err := svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), contract.ID,
+		types.TaskCompletion{DocumentURL: " https://files.example.com/contract.pdf "})
+	require.NoError(t, err)
+	assert.Equal(t, "https://files.example.com/contract.pdf", done.DocumentURL)
+
+	lunch := taskNamed(t, workflow, "Team lunch")
+	skipped, err := svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), lunch.ID, types.TaskCompletion{Skip: true})
+	require.NoError(t, err)
+	assert.Equal(t, types.TaskSkipped, skipped.Status)
+
+	laptop := taskNamed(t, workflow, "Laptop")
+	assetID := uuid.New()
+	issued, err := svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), laptop.ID,
+		types.TaskCompletion{SerialNumber: "SN-9", AssetID: &assetID})
+	require.NoError(t, err)
+	require.Len(t, repo.issued, 1)
+	assert.Equal(t, types.EquipmentAssignment{
+		ID: repo.issued[0].ID, OrganizationID: employee.OrganizationID, EmployeeID: employee.ID, Name: "Laptop",
+		SerialNumber: "SN-9", AssetID: &assetID,
+	}, repo.issued[0])
+	assert.Equal(t, &repo.issued[0].ID, issued.EquipmentAssignmentID)
+	assert.Equal(t, types.WorkflowInProgress, repo.workflows[0].State, "the email account is still pending")
+
+	account := taskNamed(t, workflow, "Email account")
+	_, err = svc.CompleteTask(ctx, employee.OrganizationID, uuid.New(), account.ID, types.TaskCompletion{})
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowCompleted, repo.workflows[0].State)
+
+	got, err := svc.GetWorkflow(ctx, employee.OrganizationID, workflow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.Progress{Total: 4, Done: 3, Skipped: 1, Percent: 100}, got.Progress)
+}
+
+func TestValidateTemplate(t *testing.T) {
+	userID := uuid.New()
+	valid := func() types.TemplateRequest {
+		return types.TemplateRequest{
+			Name: " Sales onboarding ", Kind: types.KindOnboarding,
+			Tasks: []types.TemplateTaskRequest{
+				{Name: "Welcome"},
+				{Name: "CRM account", TaskType: types.TaskTypeProvisioning, ProvisioningSystem: " CRM ", EquipmentName: "ignored"},
+				{Name: "Shadow a rep", Assignee: types.AssigneeUser, AssigneeUserID: &userID, DueOffsetDays: 14},
+			},
+		}
+	}
+
+	request := valid()
+	require.NoError(t, validateTemplate(&request))
+	assert.Equal(t, "Sales onboarding", request.Name)
+	assert.Equal(t, types.TaskTypeTask, request.Tasks[0].TaskType)
+	assert.Equal(t, types.AssigneeManager, request.Tasks[0].Assignee)
+	assert.Equal(t, 10, request.Tasks[0].Sequence)
+	assert.Equal(t, "crm", request.Tasks[1].ProvisioningSystem)
+	assert.Empty(t, request.Tasks[1].EquipmentName)
+	assert.Equal(t, 30, request.Tasks[2].Sequence)
+
+	for name, mutate := range map[string]func(*types.TemplateRequest){
+		"no name":             func(r *types.TemplateRequest) { r.Name = " " },
+		"unknown kind":        func(r *types.TemplateRequest) { r.Kind = "transfer" },
+		"no tasks":            func(r *types.TemplateRequest) { r.Tasks = nil },
+		"unnamed task":        func(r *types.TemplateRequest) { r.Tasks[0].Name = "" },
+		"unknown type":        func(r *types.TemplateRequest) { r.Tasks[0].TaskType = "meeting" },
+		"unknown assignee":    func(r *types.TemplateRequest) { r.Tasks[0].Assignee = "hr" },
+		"user without id":     func(r *types.TemplateRequest) { r.Tasks[2].AssigneeUserID = nil },
+		"due too far":         func(r *types.TemplateRequest) { r.Tasks[2].DueOffsetDays = MaxDueOffsetDays + 1 },
+		"provisioning system": func(r *types.TemplateRequest) { r.Tasks[1].ProvisioningSystem = "" },
+	} {
+		request := valid()
+		mutate(&request)
+		assert.ErrorIs(t, validateTemplate(&request), ErrInvalid, name)
+	}
+}
+
+func TestProgressCountsOverdueTasks(t *testing.T) {
+	past, future := day(time.March, 30), day(time.April, 2)
+	tasks := []types.Task{
+		{Status: types.TaskPending, DueDate: &past},
+		{Status: types.TaskFailed, DueDate: &past},
+		{Status: types.TaskDone, DueDate: &past},
+		{Status: types.TaskPending, DueDate: &future},
+		{Status: types.TaskPending},
+		{Status: types.TaskSkipped},
+	}
+	assert.Equal(t, types.Progress{Total: 6, Done: 1, Skipped: 1, Pending: 3, Failed: 1, Overdue: 2, Percent: 33.3},
+		progress(tasks, day(time.March, 31)))
+	assert.Equal(t, 100.0, progress(nil, day(time.March, 31)).Percent)
+}
@@ -0,0 +1,306 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kind is whether a checklist welcomes or sees off an employee
+type Kind string
+
+const (
+	KindOnboarding  Kind = "onboarding"
+	KindOffboarding Kind = "offboarding"
+)
+
+func (k Kind) IsValid() bool {
+	return k == KindOnboarding || k == KindOffboarding
+}
+
+// TaskType is what completing a task involves
+type TaskType string
+
+const (
+	// TaskTypeTask is a plain checklist item
+	TaskTypeTask TaskType = "task"
+	// TaskTypeDocument is done once a document is attached
+	TaskTypeDocument TaskType = "document"
+	// TaskTypeProvisioning grants or revokes an account in another system
+	TaskTypeProvisioning TaskType = "provisioning"
+	// TaskTypeEquipment hands equipment over, or takes it back on offboarding
+	TaskTypeEquipment TaskType = "equipment"
+)
+
+func (t TaskType) IsValid() bool {
+	switch t {
+	case TaskTypeTask, TaskTypeDocument, TaskTypeProvisioning, TaskTypeEquipment:
+		return true
+	}
+	return false
+}
+
+// Assignee is who a template task goes to when a workflow starts
+type Assignee string
+
+const (
+	AssigneeEmployee Assignee = "employee"
+	AssigneeManager  Assignee = "manager"
+	AssigneeUser     Assignee = "user"
+)
+
+func (a Assignee) IsValid() bool {
+	return a == AssigneeEmployee || a == AssigneeManager || a == AssigneeUser
+}
+
+// TaskStatus is where a workflow task stands
+type TaskStatus string
+
+const (
+	TaskPending TaskStatus = "pending"
+	TaskDone    TaskStatus = "done"
+	TaskSkipped TaskStatus = "skipped"
+	// TaskFailed is a provisioning task its provisioner rejected; it can be
+	// retried or completed by hand
+	TaskFailed TaskStatus = "failed"
+)
+
+// IsOpen reports whether the task still needs doing
+func (s TaskStatus) IsOpen() bool {
+	return s == TaskPending || s == TaskFailed
+}
+
+// WorkflowState is where a workflow stands
+type WorkflowState string
+
+const (
+	WorkflowInProgress WorkflowState = "in_progress"
+	WorkflowCompleted  WorkflowState = "completed"
+	WorkflowCancelled  WorkflowState = "cancelled"
+)
+
+func (s WorkflowState) IsValid() bool {
+	return s == WorkflowInProgress || s == WorkflowCompleted || s == WorkflowCancelled
+}
+
+// TemplateTask is a task copied into every workflow started from its template
+type TemplateTask struct {
+	ID                 uuid.UUID  `json:"id"`
+	Sequence           int        `json:"sequence"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description,omitempty"`
+	TaskType           TaskType   `json:"task_type"`
+	Assignee           Assignee   `json:"assignee"`
+	AssigneeUserID     *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueOffsetDays      int        `json:"due_offset_days"`
+	Required           bool       `json:"required"`
+	ProvisioningSystem string     `json:"provisioning_system,omitempty"`
+	EquipmentName      string     `json:"equipment_name,omitempty"`
+}
+
+// Template is an onboarding or offboarding checklist
+type Template struct {
+	ID             uuid.UUID      `json:"id"`
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	Name           string         `json:"name"`
+	Kind           Kind           `json:"kind"`
+	DepartmentID   *uuid.UUID     `json:"department_id,omitempty"`
+	Active         bool           `json:"active"`
+	Tasks          []TemplateTask `json:"tasks"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TemplateTaskRequest is a task of a template request
+type TemplateTaskRequest struct {
+	Sequence           int        `json:"sequence"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description,omitempty"`
+	TaskType           TaskType   `json:"task_type"`
+	Assignee           Assignee   `json:"assignee"`
+	AssigneeUserID     *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueOffsetDays      int        `json:"due_offset_days"`
+	Required           *bool      `json:"required,omitempty"`
+	ProvisioningSystem string     `json:"provisioning_system,omitempty"`
+	EquipmentName      string     `json:"equipment_name,omitempty"`
+}
+
+// TemplateRequest creates or replaces a template and its tasks
+type TemplateRequest struct {
+	Name         string                `json:"name"`
+	Kind         Kind                  `json:"kind"`
+	DepartmentID *uuid.UUID            `json:"department_id,omitempty"`
+	Active       *bool                 `json:"active,omitempty"`
+	Tasks        []TemplateTaskRequest `json:"tasks"`
+}
+
+// Employee is the part of an employee record workflows need
+type Employee struct {
+	ID             uuid.UUID
+	OrganizationID uuid.UUID
+	Name           string
+	UserID         *uuid.UUID
+	// ManagerUserID is the user of the employee's manager, or of their
+	// department's manager when they have none
+	ManagerUserID  *uuid.UUID
+	DepartmentID   *uuid.UUID
+	DateHired      *time.Time
+	DateTerminated *time.Time
+}
+
+// Task is a task of a workflow
+type Task struct {
+	ID                    uuid.UUID  `json:"id"`
+	OrganizationID        uuid.UUID  `json:"organization_id"`
+	WorkflowID            uuid.UUID  `json:"workflow_id"`
+	Sequence              int        `json:"sequence"`
+	Name                  string     `json:"name"`
+	Description           string     `json:"description,omitempty"`
+	TaskType              TaskType   `json:"task_type"`
+	AssigneeUserID        *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueDate               *time.Time `json:"due_date,omitempty"`
+	Required              bool       `json:"required"`
+	Status                TaskStatus `json:"status"`
+	ProvisioningSystem    string     `json:"provisioning_system,omitempty"`
+	EquipmentName         string     `json:"equipment_name,omitempty"`
+	EquipmentAssignmentID *uuid.UUID `json:"equipment_assignment_id,omitempty"`
+	DocumentURL           string     `json:"document_url,omitempty"`
+	Notes                 string     `json:"notes,omitempty"`
+	Error                 string     `json:"error,omitempty"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty"`
+	CompletedBy           *uuid.UUID `json:"completed_by,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// Progress counts a workflow's tasks by status
+type Progress struct {
+	Total   int     `json:"total"`
+	Done    int     `json:"done"`
+	Skipped int     `json:"skipped"`
+	Pending int     `json:"pending"`
+	Failed  int     `json:"failed"`
+	Overdue int     `json:"overdue"`
+	Percent float64 `json:"percent"`
+}
+
+// OwnershipTransfer records the CRM records moved from a departing employee
+type OwnershipTransfer struct {
+	ToUserID      uuid.UUID `json:"to_user_id"`
+	Contacts      int       `json:"contacts"`
+	Leads         int       `json:"leads"`
+	Activities    int       `json:"activities"`
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+// Workflow is one run of a checklist for one employee
+type Workflow struct {
+	ID             uuid.UUID          `json:"id"`
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	EmployeeID     uuid.UUID          `json:"employee_id"`
+	EmployeeName   string             `json:"employee_name"`
+	TemplateID     *uuid.UUID         `json:"template_id,omitempty"`
+	Kind           Kind               `json:"kind"`
+	State          WorkflowState      `json:"state"`
+	StartDate      time.Time          `json:"start_date"`
+	Ownership      *OwnershipTransfer `json:"ownership,omitempty"`
+	Tasks          []Task             `json:"tasks,omitempty"`
+	Progress       Progress           `json:"progress"`
+	CompletedAt    *time.Time         `json:"completed_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// WorkflowFilter selects workflows
+type WorkflowFilter struct {
+	OrganizationID uuid.UUID
+	EmployeeID     *uuid.UUID
+	Kind           Kind
+	State          WorkflowState
+	Limit          int
+	Offset         int
+}
+
+// StartRequest starts a workflow by hand. The template defaults to the
+// employee's department template, the start date to the hire date or the
+// last day, and on offboarding CRM records go to transfer_to, defaulting
+// to the employee's manager.
+type StartRequest struct {
+	EmployeeID uuid.UUID  `json:"employee_id"`
+	Kind       Kind       `json:"kind"`
+	TemplateID *uuid.UUID `json:"template_id,omitempty"`
+	StartDate  *time.Time `json:"start_date,omitempty"`
+	TransferTo *uuid.UUID `json:"transfer_to,omitempty"`
+}
+
+// EmployeeEvent is the payload of the HR events that start workflows
+type EmployeeEvent struct {
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	EmployeeID     uuid.UUID  `json:"employee_id"`
+	Date           *time.Time `json:"date,omitempty"`
+	TransferTo     *uuid.UUID `json:"transfer_to,omitempty"`
+}
+
+// TaskCompletion completes or skips a task. Document tasks need a
+// document_url; equipment tasks on onboarding record the equipment handed
+// over, and on offboarding mark it returned.
+type TaskCompletion struct {
+	Skip         bool       `json:"skip,omitempty"`
+	DocumentURL  string     `json:"document_url,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	SerialNumber string     `json:"serial_number,omitempty"`
+	AssetID      *uuid.UUID `json:"asset_id,omitempty"`
+}
+
+// TransferRequest moves a departing employee's CRM records to another user
+type TransferRequest struct {
+	ToUserID uuid.UUID `json:"to_user_id"`
+}
+
+// ProvisioningAction is what a provisioner does with an account
+type ProvisioningAction string
+
+const (
+	ProvisioningGrant  ProvisioningAction = "grant"
+	ProvisioningRevoke ProvisioningAction = "revoke"
+)
+
+// ProvisioningRequest asks a provisioner to grant or revoke an employee's
+// account in its system
+type ProvisioningRequest struct {
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	EmployeeID     uuid.UUID          `json:"employee_id"`
+	EmployeeName   string             `json:"employee_name"`
+	UserID         *uuid.UUID         `json:"user_id,omitempty"`
+	TaskID         uuid.UUID          `json:"task_id"`
+	System         string             `json:"system"`
+	Action         ProvisioningAction `json:"action"`
+}
+
+// EquipmentAssignment is equipment handed to an employee
+type EquipmentAssignment struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	EmployeeID     uuid.UUID  `json:"employee_id"`
+	Name           string     `json:"name"`
+	SerialNumber   string     `json:"serial_number,omitempty"`
+	AssetID        *uuid.UUID `json:"asset_id,omitempty"`
+	AssignedAt     time.Time  `json:"assigned_at"`
+	ReturnedAt     *time.Time `json:"returned_at,omitempty"`
+}
+
+// EquipmentFilter selects equipment assignments
+type EquipmentFilter struct {
+	OrganizationID uuid.UUID
+	EmployeeID     *uuid.UUID
+	OpenOnly       bool
+}
+
+// TaskFilter selects tasks of open workflows
+type TaskFilter struct {
+	OrganizationID uuid.UUID
+	AssigneeUserID *uuid.UUID
+	OpenOnly       bool
+	Limit          int
+	Offset         int
+}
@@ -0,0 +1,531 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PartnerPathPrefix is the API surface partner-role users are limited to
+const PartnerPathPrefix = "/api/v1/partner/"
+
+// partnerAllowedPrefixes are reachable by partner-role users besides the
+// partner API: signing in and out, their profile and the public endpoints
+var partnerAllowedPrefixes = []string{
+	PartnerPathPrefix,
+	"/auth/",
+	"/public/",
+}
+
+// PartnersHandler handles partner management and deal registration
+// decisions for staff, and the partner API partner users reach
+type PartnersHandler struct {
+	service *service.PartnersService
+}
+
+func NewPartnersHandler(service *service.PartnersService) *PartnersHandler {
+	return &PartnersHandler{service: service}
+}
+
+func (h *PartnersHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/partners", h.ListPartners)
+	router.POST("/api/v1/partners", h.CreatePartner)
+	router.GET("/api/v1/partners/:id", h.GetPartner)
+	router.PUT("/api/v1/partners/:id", h.UpdatePartner)
+	router.DELETE("/api/v1/partners/:id", h.DeletePartner)
+	router.POST("/api/v1/partners/:id/users", h.AddPartnerUser)
+	router.DELETE("/api/v1/partners/:id/users/:user_id", h.RemovePartnerUser)
+
+	router.GET("/api/v1/partner-registrations", h.ListRegistrations)
+	router.GET("/api/v1/partner-registrations/:id", h.GetRegistration)
+	router.GET("/api/v1/partner-registrations/:id/conflicts", h.RegistrationConflicts)
+	router.POST("/api/v1/partner-registrations/:id/approve", h.ApproveRegistration)
+	router.POST("/api/v1/partner-registrations/:id/reject", h.RejectRegistration)
+
+	// Partner API: scoped to the calling user's partner
+	router.GET(PartnerPathPrefix+"profile", h.MyPartner)
+	router.GET(PartnerPathPrefix+"registrations", h.MyRegistrations)
+	router.POST(PartnerPathPrefix+"registrations", h.RegisterDeal)
+	router.GET(PartnerPathPrefix+"registrations/:id", h.MyRegistration)
+	router.GET(PartnerPathPrefix+"pipeline", h.MyPipeline)
+	router.GET(PartnerPathPrefix+"commissions", h.MyCommissionStatement)
+}
+
+// Middleware keeps partner-role users on the partner API; every other
+// endpoint answers them 403 whatever their policies allow
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authCtx, err := auth.FromContext(r.Context())
+		if err != nil || !authCtx.HasRole(types.RolePartner) || partnerAllowed(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "permission denied: partner users are limited to "+PartnerPathPrefix, http.StatusForbidden)
+	})
+}
+
+func partnerAllowed(path string) bool {
+	for _, prefix := range partnerAllowedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return path == "/" || path == "/health"
+}
+
+// ListPartners handles GET /api/v1/partners
+func (h *PartnersHandler) ListPartners(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	partners, err := h.service.ListPartners(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partners)
+}
+
+// CreatePartner handles POST /api/v1/partners
+func (h *PartnersHandler) CreatePartner(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.CreatePartner(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, partner)
+}
+
+// GetPartner handles GET /api/v1/partners/:id
+func (h *PartnersHandler) GetPartner(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.GetPartner(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// UpdatePartner handles PUT /api/v1/partners/:id
+func (h *PartnersHandler) UpdatePartner(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.UpdatePartner(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// DeletePartner handles DELETE /api/v1/partners/:id
+func (h *PartnersHandler) DeletePartner(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeletePartner(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddPartnerUser handles POST /api/v1/partners/:id/users
+func (h *PartnersHandler) AddPartnerUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PartnerUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.AddPartnerUser(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// RemovePartnerUser handles DELETE /api/v1/partners/:id/users/:user_id
+func (h *PartnersHandler) RemovePartnerUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+		return
+	}
+	userID, err := uuid.Parse(ps.ByName("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemovePartnerUser(r.Context(), authCtx.OrganizationID, id, userID); err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRegistrations handles GET /api/v1/partner-registrations with optional
+// partner_id and status filters
+func (h *PartnersHandler) ListRegistrations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.RegistrationFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.RegistrationStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("partner_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid partner ID", http.StatusBadRequest)
+			return
+		}
+		filter.PartnerID = &id
+	}
+
+	registrations, err := h.service.ListRegistrations(r.Context(), filter)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registrations)
+}
+
+// GetRegistration handles GET /api/v1/partner-registrations/:id
+func (h *PartnersHandler) GetRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid registration ID", http.StatusBadRequest)
+		return
+	}
+
+	registration, err := h.service.GetRegistration(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registration)
+}
+
+// RegistrationConflicts handles GET /api/v1/partner-registrations/:id/conflicts
+func (h *PartnersHandler) RegistrationConflicts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid registration ID", http.StatusBadRequest)
+		return
+	}
+
+	leads, err := h.service.RegistrationConflicts(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, leads)
+}
+
+// ApproveRegistration handles POST /api/v1/partner-registrations/:id/approve
+func (h *PartnersHandler) ApproveRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.decide(w, r, ps, h.service.ApproveRegistration)
+}
+
+// RejectRegistration handles POST /api/v1/partner-registrations/:id/reject
+func (h *PartnersHandler) RejectRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.decide(w, r, ps, h.service.RejectRegistration)
+}
+
+type decideFunc func(ctx context.Context, orgID, userID, id uuid.UUID, req types.DecisionRequest) (*types.DealRegistration, error)
+
+func (h *PartnersHandler) decide(w http.ResponseWriter, r *http.Request, ps httprouter.Params, decide decideFunc) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid registration ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	registration, err := decide(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registration)
+}
+
+// MyPartner handles GET /api/v1/partner/profile
+func (h *PartnersHandler) MyPartner(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	partner, err := h.service.MyPartner(r.Context(), authCtx.OrganizationID, authCtx.UserID)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partner)
+}
+
+// MyRegistrations handles GET /api/v1/partner/registrations with an
+// optional status filter
+func (h *PartnersHandler) MyRegistrations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.RegistrationFilter{
+		Status: types.RegistrationStatus(q.Get("status")),
+		Limit:  queryInt(q.Get("limit")),
+		Offset: queryInt(q.Get("offset")),
+	}
+
+	registrations, err := h.service.MyRegistrations(r.Context(), authCtx.OrganizationID, authCtx.UserID, filter)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registrations)
+}
+
+// RegisterDeal handles POST /api/v1/partner/registrations
+func (h *PartnersHandler) RegisterDeal(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.DealRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registration, err := h.service.RegisterDeal(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, registration)
+}
+
+// MyRegistration handles GET /api/v1/partner/registrations/:id
+func (h *PartnersHandler) MyRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid registration ID", http.StatusBadRequest)
+		return
+	}
+
+	registration, err := h.service.MyRegistration(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registration)
+}
+
+// MyPipeline handles GET /api/v1/partner/pipeline with an optional status
+// filter
+func (h *PartnersHandler) MyPipeline(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.PipelineFilter{
+		Status: q.Get("status"),
+		Limit:  queryInt(q.Get("limit")),
+		Offset: queryInt(q.Get("offset")),
+	}
+
+	leads, err := h.service.MyPipeline(r.Context(), authCtx.OrganizationID, authCtx.UserID, filter)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, leads)
+}
+
+// MyCommissionStatement handles GET /api/v1/partner/commissions with
+// optional from and to dates (YYYY-MM-DD)
+func (h *PartnersHandler) MyCommissionStatement(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var from, to time.Time
+	for name, dest := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = t
+		}
+	}
+
+	statement, err := h.service.MyCommissionStatement(r.Context(), authCtx.OrganizationID, authCtx.UserID, from, to)
+	if err != nil {
+		writePartnersError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statement)
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writePartnersError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"), errors.Is(err, service.ErrNotPartner):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, repository.ErrNotPartnerRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrConflict), errors.Is(err, repository.ErrDecided), errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,84 @@
+package partners
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PartnersModule represents the partner portal module
+type PartnersModule struct {
+	partnersService *service.PartnersService
+	partnersHandler *handler.PartnersHandler
+	logger          *slog.Logger
+}
+
+// NewPartnersModule creates a new partners module
+func NewPartnersModule() *PartnersModule {
+	return &PartnersModule{}
+}
+
+// Name returns the module name
+func (m *PartnersModule) Name() string {
+	return "partners"
+}
+
+// SetMailer emails partners the decisions on their deal registrations. It
+// must be called after Init.
+func (m *PartnersModule) SetMailer(mailer service.Mailer) {
+	if m.partnersService != nil {
+		m.partnersService.SetMailer(mailer)
+	}
+}
+
+// Middleware keeps partner-role users on the partner API. It must wrap the
+// router inside the auth middleware, which resolves their role.
+func (m *PartnersModule) Middleware(next http.Handler) http.Handler {
+	return handler.Middleware(next)
+}
+
+// Init initializes the partners module
+func (m *PartnersModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "partners")
+	m.logger.Info("Initializing partners module")
+
+	// Create repositories
+	partnersRepo := repository.NewPartnersRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.partnersService = service.NewPartnersService(partnersRepo, authAdapter, deps.EventBus, m.logger)
+
+	// Create handlers
+	m.partnersHandler = handler.NewPartnersHandler(m.partnersService)
+
+	m.logger.Info("Partners module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers partners module routes
+func (m *PartnersModule) RegisterRoutes(router interface{}) {
+	if m.partnersHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.partnersHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the partners module
+func (m *PartnersModule) RegisterEventHandlers(bus interface{}) {
+	// Partners publish deal registration events but consume none
+}
+
+// Health checks the health of the partners module
+func (m *PartnersModule) Health() error {
+	return nil
+}
@@ -0,0 +1,563 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a partner, partner user or deal
+	// registration does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a partner name is taken or a user
+	// already belongs to a partner
+	ErrDuplicate = errors.New("already exists")
+	// ErrNotPartnerRole is returned when adding a user to a partner who is
+	// not an active member of the organization with the partner role
+	ErrNotPartnerRole = errors.New("user is not an active partner-role member of the organization")
+	// ErrDecided is returned when deciding a deal registration that was
+	// already approved or rejected
+	ErrDecided = errors.New("deal registration was already decided")
+)
+
+// PartnersRepo defines the interface for partners repository operations
+type PartnersRepo interface {
+	ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error)
+	FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error)
+	CreatePartner(ctx context.Context, orgID, userID uuid.UUID, request types.PartnerRequest) (*types.Partner, error)
+	UpdatePartner(ctx context.Context, orgID, userID, id uuid.UUID, request types.PartnerRequest) (*types.Partner, error)
+	DeletePartner(ctx context.Context, orgID, id uuid.UUID) error
+	AddPartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error
+	RemovePartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error
+	PartnerForUser(ctx context.Context, orgID, userID uuid.UUID) (*types.Partner, error)
+	FindConflicts(ctx context.Context, query types.ConflictQuery) ([]types.ConflictingLead, error)
+	CreateRegistration(ctx context.Context, registration types.DealRegistration) (*types.DealRegistration, error)
+	FindRegistration(ctx context.Context, orgID, id uuid.UUID) (*types.DealRegistration, error)
+	ListRegistrations(ctx context.Context, filter types.RegistrationFilter) ([]types.DealRegistration, error)
+	ApproveRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error)
+	RejectRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error)
+	Pipeline(ctx context.Context, filter types.PipelineFilter) ([]types.PipelineLead, error)
+	CommissionEntries(ctx context.Context, orgID, partnerID uuid.UUID, from, to time.Time) ([]types.CommissionEntry, error)
+}
+
+// PartnersRepository stores partners, their users and deal registrations,
+// and reads the leads and commissions attributed to them
+type PartnersRepository struct {
+	db *sql.DB
+}
+
+// Ensure PartnersRepository implements PartnersRepo interface
+var _ PartnersRepo = &PartnersRepository{}
+
+func NewPartnersRepository(db *sql.DB) *PartnersRepository {
+	return &PartnersRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func scanUUIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+const partnerColumns = `
+	p.id, p.organization_id, p.name, p.email, p.active,
+	ARRAY(SELECT pu.user_id::text FROM partner_users pu WHERE pu.partner_id = p.id ORDER BY pu.created_at),
+	p.created_at, p.updated_at, p.created_by, p.updated_by
+`
+
+func scanPartner(row rowScanner) (*types.Partner, error) {
+	var p types.Partner
+	var userIDs []string
+	var createdBy, updatedBy uuid.NullUUID
+	err := row.Scan(&p.ID, &p.OrganizationID, &p.Name, &p.Email, &p.Active, pq.Array(&userIDs),
+		&p.CreatedAt, &p.UpdatedAt, &createdBy, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserIDs, err = scanUUIDs(userIDs); err != nil {
+		return nil, err
+	}
+	p.CreatedBy = nullUUID(createdBy)
+	p.UpdatedBy = nullUUID(updatedBy)
+	return &p, nil
+}
+
+func (r *PartnersRepository) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+partnerColumns+` FROM partners p
+		WHERE p.organization_id = $1
+		ORDER BY p.name, p.id
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partners: %w", err)
+	}
+	defer rows.Close()
+
+	partners := []types.Partner{}
+	for rows.Next() {
+		p, err := scanPartner(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan partner: %w", err)
+		}
+		partners = append(partners, *p)
+	}
+	return partners, rows.Err()
+}
+
+func (r *PartnersRepository) FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	p, err := scanPartner(r.db.QueryRowContext(ctx, `
+		SELECT `+partnerColumns+` FROM partners p WHERE p.id = $1 AND p.organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("partner %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find partner: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PartnersRepository) CreatePartner(ctx context.Context, orgID, userID uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	active := request.Active == nil || *request.Active
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO partners (organization_id, name, email, active, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id
+	`, orgID, request.Name, request.Email, active, userID).Scan(&id)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("partner named %q %w", request.Name, ErrDuplicate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create partner: %w", err)
+	}
+	return r.FindPartner(ctx, orgID, id)
+}
+
+func (r *PartnersRepository) UpdatePartner(ctx context.Context, orgID, userID, id uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	active := request.Active == nil || *request.Active
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE partners SET name = $3, email = $4, active = $5, updated_by = $6, updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID, request.Name, request.Email, active, userID)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("partner named %q %w", request.Name, ErrDuplicate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update partner: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("partner %w", ErrNotFound)
+	}
+	return r.FindPartner(ctx, orgID, id)
+}
+
+// DeletePartner removes a partner with its users and deal registrations.
+// Its leads stay, no longer attributed.
+func (r *PartnersRepository) DeletePartner(ctx context.Context, orgID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM partners WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete partner: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("partner %w", ErrNotFound)
+	}
+	return nil
+}
+
+// AddPartnerUser adds an active partner-role member of the organization to
+// a partner. A user belongs to at most one partner.
+func (r *PartnersRepository) AddPartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error {
+	if _, err := r.FindPartner(ctx, orgID, partnerID); err != nil {
+		return err
+	}
+
+	var isPartner bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_users
+			WHERE organization_id = $1 AND user_id = $2 AND role = 'partner' AND is_active
+		)
+	`, orgID, userID).Scan(&isPartner)
+	if err != nil {
+		return fmt.Errorf("failed to check partner user role: %w", err)
+	}
+	if !isPartner {
+		return ErrNotPartnerRole
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO partner_users (partner_id, organization_id, user_id) VALUES ($1, $2, $3)
+	`, partnerID, orgID, userID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("partner user %w", ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add partner user: %w", err)
+	}
+	return nil
+}
+
+func (r *PartnersRepository) RemovePartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM partner_users WHERE partner_id = $1 AND organization_id = $2 AND user_id = $3
+	`, partnerID, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove partner user: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("partner user %w", ErrNotFound)
+	}
+	return nil
+}
+
+// PartnerForUser returns the partner a user belongs to
+func (r *PartnersRepository) PartnerForUser(ctx context.Context, orgID, userID uuid.UUID) (*types.Partner, error) {
+	p, err := scanPartner(r.db.QueryRowContext(ctx, `
+		SELECT `+partnerColumns+` FROM partners p
+		JOIN partner_users u ON u.partner_id = p.id
+		WHERE u.organization_id = $1 AND u.user_id = $2
+	`, orgID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("partner %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find partner of user: %w", err)
+	}
+	return p, nil
+}
+
+// FindConflicts returns the open leads matching the query's email, email
+// domain or company name, case-insensitively
+func (r *PartnersRepository) FindConflicts(ctx context.Context, query types.ConflictQuery) ([]types.ConflictingLead, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id, l.name, COALESCE(l.contact_name, ''), COALESCE(l.email, ''), l.status, l.partner_id, l.assigned_to
+		FROM leads l
+		WHERE l.organization_id = $1
+			AND l.deleted_at IS NULL
+			AND l.status IN ('new', 'in_progress')
+			AND (
+				($2 <> '' AND lower(l.email) = $2)
+				OR ($3 <> '' AND lower(split_part(l.email, '@', 2)) = $3)
+				OR ($4 <> '' AND lower(trim(l.name)) = $4)
+			)
+		ORDER BY l.created_at, l.id
+	`, query.OrganizationID, strings.ToLower(query.Email), strings.ToLower(query.Domain),
+		strings.ToLower(strings.TrimSpace(query.CompanyName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conflicting leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []types.ConflictingLead{}
+	for rows.Next() {
+		var l types.ConflictingLead
+		var partnerID, assignedTo uuid.NullUUID
+		if err := rows.Scan(&l.ID, &l.Name, &l.ContactName, &l.Email, &l.Status, &partnerID, &assignedTo); err != nil {
+			return nil, fmt.Errorf("failed to scan conflicting lead: %w", err)
+		}
+		l.PartnerID = nullUUID(partnerID)
+		l.AssignedTo = nullUUID(assignedTo)
+		leads = append(leads, l)
+	}
+	return leads, rows.Err()
+}
+
+const registrationColumns = `
+	id, organization_id, partner_id, registered_by, company_name, contact_name, email, phone, expected_revenue,
+	description, status, conflict_lead_ids::text[], lead_id, decision_note, decided_by, decided_at, created_at
+`
+
+func scanRegistration(row rowScanner) (*types.DealRegistration, error) {
+	var reg types.DealRegistration
+	var revenue sql.NullFloat64
+	var conflicts []string
+	var leadID, decidedBy uuid.NullUUID
+	var decidedAt sql.NullTime
+	err := row.Scan(&reg.ID, &reg.OrganizationID, &reg.PartnerID, &reg.RegisteredBy, &reg.CompanyName,
+		&reg.ContactName, &reg.Email, &reg.Phone, &revenue, &reg.Description, &reg.Status, pq.Array(&conflicts),
+		&leadID, &reg.DecisionNote, &decidedBy, &decidedAt, &reg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if reg.ConflictLeadIDs, err = scanUUIDs(conflicts); err != nil {
+		return nil, err
+	}
+	reg.HasConflict = len(reg.ConflictLeadIDs) > 0
+	reg.ExpectedRevenue = nullFloat(revenue)
+	reg.LeadID = nullUUID(leadID)
+	reg.DecidedBy = nullUUID(decidedBy)
+	reg.DecidedAt = nullTime(decidedAt)
+	return &reg, nil
+}
+
+func (r *PartnersRepository) CreateRegistration(ctx context.Context, registration types.DealRegistration) (*types.DealRegistration, error) {
+	reg, err := scanRegistration(r.db.QueryRowContext(ctx, `
+		INSERT INTO partner_deal_registrations (
+			organization_id, partner_id, registered_by, company_name, contact_name, email, phone,
+			expected_revenue, description, conflict_lead_ids
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+registrationColumns,
+		registration.OrganizationID, registration.PartnerID, registration.RegisteredBy, registration.CompanyName,
+		registration.ContactName, registration.Email, registration.Phone, registration.ExpectedRevenue,
+		registration.Description, pq.Array(registration.ConflictLeadIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deal registration: %w", err)
+	}
+	return reg, nil
+}
+
+func (r *PartnersRepository) FindRegistration(ctx context.Context, orgID, id uuid.UUID) (*types.DealRegistration, error) {
+	reg, err := scanRegistration(r.db.QueryRowContext(ctx, `
+		SELECT `+registrationColumns+` FROM partner_deal_registrations WHERE id = $1 AND organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("deal registration %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deal registration: %w", err)
+	}
+	return reg, nil
+}
+
+func (r *PartnersRepository) ListRegistrations(ctx context.Context, filter types.RegistrationFilter) ([]types.DealRegistration, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.PartnerID != nil {
+		args = append(args, *filter.PartnerID)
+		where = append(where, fmt.Sprintf("partner_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+registrationColumns+` FROM partner_deal_registrations
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deal registrations: %w", err)
+	}
+	defer rows.Close()
+
+	registrations := []types.DealRegistration{}
+	for rows.Next() {
+		reg, err := scanRegistration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deal registration: %w", err)
+		}
+		registrations = append(registrations, *reg)
+	}
+	return registrations, rows.Err()
+}
+
+// ApproveRegistration approves a pending deal registration and creates the
+// partner's lead from it, in one transaction
+func (r *PartnersRepository) ApproveRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reg, err := lockPending(ctx, tx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var leadID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO leads (
+			organization_id, name, contact_name, email, phone, expected_revenue, description, partner_id,
+			date_open, created_by, updated_by
+		) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''), $8, $9, $10, $10)
+		RETURNING id
+	`, orgID, reg.CompanyName, reg.ContactName, reg.Email, reg.Phone, reg.ExpectedRevenue, reg.Description,
+		reg.PartnerID, at, userID).Scan(&leadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create partner lead: %w", err)
+	}
+
+	decided, err := scanRegistration(tx.QueryRowContext(ctx, `
+		UPDATE partner_deal_registrations SET
+			status = 'approved', lead_id = $3, conflict_lead_ids = $4, decision_note = $5, decided_by = $6, decided_at = $7
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+registrationColumns,
+		id, orgID, leadID, pq.Array(conflicts), note, userID, at))
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve deal registration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return decided, nil
+}
+
+// RejectRegistration rejects a pending deal registration
+func (r *PartnersRepository) RejectRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPending(ctx, tx, orgID, id); err != nil {
+		return nil, err
+	}
+
+	decided, err := scanRegistration(tx.QueryRowContext(ctx, `
+		UPDATE partner_deal_registrations SET
+			status = 'rejected', conflict_lead_ids = $3, decision_note = $4, decided_by = $5, decided_at = $6
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+registrationColumns,
+		id, orgID, pq.Array(conflicts), note, userID, at))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject deal registration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return decided, nil
+}
+
+// lockPending locks a deal registration for its decision, which only a
+// pending one can take
+func lockPending(ctx context.Context, tx *sql.Tx, orgID, id uuid.UUID) (*types.DealRegistration, error) {
+	reg, err := scanRegistration(tx.QueryRowContext(ctx, `
+		SELECT `+registrationColumns+` FROM partner_deal_registrations
+		WHERE id = $1 AND organization_id = $2
+		FOR UPDATE
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("deal registration %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock deal registration: %w", err)
+	}
+	if reg.Status != types.RegistrationPending {
+		return nil, ErrDecided
+	}
+	return reg, nil
+}
+
+// Pipeline returns the leads attributed to a partner, newest first
+func (r *PartnersRepository) Pipeline(ctx context.Context, filter types.PipelineFilter) ([]types.PipelineLead, error) {
+	where := []string{"l.organization_id = $1", "l.partner_id = $2", "l.deleted_at IS NULL"}
+	args := []interface{}{filter.OrganizationID, filter.PartnerID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("l.status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id, l.name, COALESCE(l.contact_name, ''), COALESCE(l.email, ''), l.status, COALESCE(s.name, ''),
+			l.expected_revenue, COALESCE(l.probability, 0), l.created_at, l.date_closed
+		FROM leads l
+		LEFT JOIN lead_stages s ON s.id = l.stage_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY l.created_at DESC, l.id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partner pipeline: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []types.PipelineLead{}
+	for rows.Next() {
+		var l types.PipelineLead
+		var revenue sql.NullFloat64
+		var closed sql.NullTime
+		if err := rows.Scan(&l.ID, &l.Name, &l.ContactName, &l.Email, &l.Status, &l.Stage, &revenue,
+			&l.Probability, &l.CreatedAt, &closed); err != nil {
+			return nil, fmt.Errorf("failed to scan partner lead: %w", err)
+		}
+		l.ExpectedRevenue = nullFloat(revenue)
+		l.DateClosed = nullTime(closed)
+		leads = append(leads, l)
+	}
+	return leads, rows.Err()
+}
+
+// CommissionEntries returns the commission entries of a partner's users
+// earned between from and to, inclusive
+func (r *PartnersRepository) CommissionEntries(ctx context.Context, orgID, partnerID uuid.UUID, from, to time.Time) ([]types.CommissionEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.id, e.user_id, e.entry_type, COALESCE(e.source_type, ''), e.source_id, COALESCE(e.source_name, ''),
+			e.earned_on, e.amount, e.payout_id IS NOT NULL
+		FROM commission_entries e
+		JOIN partner_users u ON u.user_id = e.user_id AND u.organization_id = e.organization_id
+		WHERE e.organization_id = $1 AND u.partner_id = $2 AND e.earned_on BETWEEN $3 AND $4
+		ORDER BY e.earned_on, e.created_at, e.id
+	`, orgID, partnerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partner commissions: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.CommissionEntry{}
+	for rows.Next() {
+		var e types.CommissionEntry
+		var sourceID uuid.NullUUID
+		if err := rows.Scan(&e.ID, &e.UserID, &e.EntryType, &e.SourceType, &sourceID, &e.SourceName,
+			&e.EarnedOn, &e.Amount, &e.Paid); err != nil {
+			return nil, fmt.Errorf("failed to scan partner commission: %w", err)
+		}
+		e.SourceID = nullUUID(sourceID)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
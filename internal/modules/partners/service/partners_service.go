@@ -0,0 +1,602 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPageSize is the page size of registration and pipeline lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of registration and pipeline lists
+	MaxPageSize = 500
+	// MaxStatementDays bounds the period of a commission statement
+	MaxStatementDays = 366
+	// sendTimeout bounds the delivery of one decision email
+	sendTimeout = 30 * time.Second
+	// maxDescription bounds the description of a deal registration
+	maxDescription = 4000
+)
+
+// Events published as deal registrations move
+const (
+	EventDealRegistered = "partners.deal_registered"
+	EventDealApproved   = "partners.deal_approved"
+	EventDealRejected   = "partners.deal_rejected"
+)
+
+var (
+	// ErrInvalid wraps validation failures of partners, registrations and filters
+	ErrInvalid = errors.New("invalid request")
+	// ErrNotPartner is returned when a partner API caller does not belong to
+	// an active partner
+	ErrNotPartner = errors.New("user does not belong to an active partner")
+	// ErrConflict is returned when approving a deal registration that
+	// conflicts with open leads without overriding
+	ErrConflict = errors.New("deal registration conflicts with open leads")
+)
+
+// freeMailDomains are shared by unrelated customers, so matching on them
+// would flag every lead using them as a conflict
+var freeMailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"yahoo.com":      true,
+	"hotmail.com":    true,
+	"outlook.com":    true,
+	"live.com":       true,
+	"icloud.com":     true,
+	"aol.com":        true,
+	"proton.me":      true,
+	"protonmail.com": true,
+}
+
+// AuthService defines the permission check used by the partners service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer delivers deal registration decisions to partners by email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// PartnersService runs the partner portal: partners register deals, which
+// are checked against open leads and approved into partner-attributed leads
+// or rejected, and follow their own pipeline and commissions
+type PartnersService struct {
+	repo        repository.PartnersRepo
+	authService AuthService
+	eventBus    *events.Bus
+	mailer      Mailer
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewPartnersService(repo repository.PartnersRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *PartnersService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PartnersService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails partners the decisions on their deal registrations.
+// Without a mailer partners only see them through the API.
+func (s *PartnersService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// ListPartners returns the organization's partners
+func (s *PartnersService) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListPartners(ctx, orgID)
+}
+
+// GetPartner returns a partner
+func (s *PartnersService) GetPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindPartner(ctx, orgID, id)
+}
+
+// CreatePartner creates a partner
+func (s *PartnersService) CreatePartner(ctx context.Context, orgID, userID uuid.UUID, req types.PartnerRequest) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizePartner(&req); err != nil {
+		return nil, err
+	}
+	return s.repo.CreatePartner(ctx, orgID, userID, req)
+}
+
+// UpdatePartner replaces a partner's details
+func (s *PartnersService) UpdatePartner(ctx context.Context, orgID, userID, id uuid.UUID, req types.PartnerRequest) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizePartner(&req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdatePartner(ctx, orgID, userID, id, req)
+}
+
+// DeletePartner removes a partner with its users and deal registrations
+func (s *PartnersService) DeletePartner(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeletePartner(ctx, orgID, id)
+}
+
+// AddPartnerUser gives a partner-role member of the organization access to
+// a partner's portal
+func (s *PartnersService) AddPartnerUser(ctx context.Context, orgID, partnerID uuid.UUID, req types.PartnerUserRequest) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if req.UserID == uuid.Nil {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalid)
+	}
+	if err := s.repo.AddPartnerUser(ctx, orgID, partnerID, req.UserID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPartner(ctx, orgID, partnerID)
+}
+
+// RemovePartnerUser takes a user off a partner
+func (s *PartnersService) RemovePartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.RemovePartnerUser(ctx, orgID, partnerID, userID)
+}
+
+func normalizePartner(req *types.PartnerRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return fmt.Errorf("%w: email %q is not a valid address", ErrInvalid, req.Email)
+		}
+	}
+	return nil
+}
+
+// ListRegistrations returns deal registrations of all partners, newest first
+func (s *PartnersService) ListRegistrations(ctx context.Context, filter types.RegistrationFilter) ([]types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizeRegistrationFilter(&filter); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRegistrations(ctx, filter)
+}
+
+// GetRegistration returns a deal registration
+func (s *PartnersService) GetRegistration(ctx context.Context, orgID, id uuid.UUID) (*types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindRegistration(ctx, orgID, id)
+}
+
+// RegistrationConflicts returns the open leads a deal registration
+// conflicts with now
+func (s *PartnersService) RegistrationConflicts(ctx context.Context, orgID, id uuid.UUID) ([]types.ConflictingLead, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	reg, err := s.repo.FindRegistration(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.conflicts(ctx, orgID, reg.Email, reg.CompanyName)
+}
+
+// ApproveRegistration approves a pending deal registration, creating the
+// lead attributed to its partner. The conflicts are checked again, since
+// leads may have been created since the deal was registered, and refused
+// unless req overrides them.
+func (s *PartnersService) ApproveRegistration(ctx context.Context, orgID, userID, id uuid.UUID, req types.DecisionRequest) (*types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	reg, err := s.repo.FindRegistration(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if reg.Status != types.RegistrationPending {
+		return nil, repository.ErrDecided
+	}
+	conflicts, err := s.conflicts(ctx, orgID, reg.Email, reg.CompanyName)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 && !req.Override {
+		return nil, fmt.Errorf("%w: %d open leads match; approve with override to register the deal anyway", ErrConflict, len(conflicts))
+	}
+
+	decided, err := s.repo.ApproveRegistration(ctx, orgID, id, userID, strings.TrimSpace(req.Note), conflictIDs(conflicts), s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.decided(ctx, EventDealApproved, decided)
+	return decided, nil
+}
+
+// RejectRegistration rejects a pending deal registration
+func (s *PartnersService) RejectRegistration(ctx context.Context, orgID, userID, id uuid.UUID, req types.DecisionRequest) (*types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partners:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	reg, err := s.repo.FindRegistration(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := s.conflicts(ctx, orgID, reg.Email, reg.CompanyName)
+	if err != nil {
+		return nil, err
+	}
+
+	decided, err := s.repo.RejectRegistration(ctx, orgID, id, userID, strings.TrimSpace(req.Note), conflictIDs(conflicts), s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.decided(ctx, EventDealRejected, decided)
+	return decided, nil
+}
+
+// MyPartner returns the partner of the calling partner user
+func (s *PartnersService) MyPartner(ctx context.Context, orgID, userID uuid.UUID) (*types.Partner, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Partners do not see who else works for them
+	partner.UserIDs = nil
+	return partner, nil
+}
+
+// RegisterDeal registers a deal for the calling user's partner. The open
+// leads it conflicts with are recorded for whoever decides it.
+func (s *PartnersService) RegisterDeal(ctx context.Context, orgID, userID uuid.UUID, req types.DealRegistrationRequest) (*types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:register"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeRegistration(&req); err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.conflicts(ctx, orgID, req.Email, req.CompanyName)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := s.repo.CreateRegistration(ctx, types.DealRegistration{
+		OrganizationID:  orgID,
+		PartnerID:       partner.ID,
+		RegisteredBy:    userID,
+		CompanyName:     req.CompanyName,
+		ContactName:     req.ContactName,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		ExpectedRevenue: req.ExpectedRevenue,
+		Description:     req.Description,
+		ConflictLeadIDs: conflictIDs(conflicts),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, EventDealRegistered, reg)
+	return forPartner(reg), nil
+}
+
+// MyRegistrations returns the deal registrations of the calling user's
+// partner, newest first
+func (s *PartnersService) MyRegistrations(ctx context.Context, orgID, userID uuid.UUID, filter types.RegistrationFilter) ([]types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.OrganizationID = orgID
+	filter.PartnerID = &partner.ID
+	if err := normalizeRegistrationFilter(&filter); err != nil {
+		return nil, err
+	}
+	registrations, err := s.repo.ListRegistrations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range registrations {
+		registrations[i] = *forPartner(&registrations[i])
+	}
+	return registrations, nil
+}
+
+// MyRegistration returns one of the deal registrations of the calling
+// user's partner
+func (s *PartnersService) MyRegistration(ctx context.Context, orgID, userID, id uuid.UUID) (*types.DealRegistration, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := s.repo.FindRegistration(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	// Another partner's registration does not exist as far as this one knows
+	if reg.PartnerID != partner.ID {
+		return nil, fmt.Errorf("deal registration %w", repository.ErrNotFound)
+	}
+	return forPartner(reg), nil
+}
+
+// MyPipeline returns the leads attributed to the calling user's partner
+func (s *PartnersService) MyPipeline(ctx context.Context, orgID, userID uuid.UUID, filter types.PipelineFilter) ([]types.PipelineLead, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.OrganizationID = orgID
+	filter.PartnerID = partner.ID
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.Pipeline(ctx, filter)
+}
+
+// MyCommissionStatement returns what the calling user's partner earned
+// between from and to. The period defaults to the month of to, which
+// defaults to today.
+func (s *PartnersService) MyCommissionStatement(ctx context.Context, orgID, userID uuid.UUID, from, to time.Time) (*types.CommissionStatement, error) {
+	if err := s.authService.CheckPermission(ctx, "partner_portal:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	partner, err := s.partnerOf(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if to.IsZero() {
+		to = s.now()
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if from.IsZero() {
+		from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalid)
+	}
+	if to.Sub(from) > MaxStatementDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: a statement covers at most %d days", ErrInvalid, MaxStatementDays)
+	}
+
+	entries, err := s.repo.CommissionEntries(ctx, orgID, partner.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return BuildStatement(partner.ID, from, to, entries), nil
+}
+
+// BuildStatement totals commission entries into a statement
+func BuildStatement(partnerID uuid.UUID, from, to time.Time, entries []types.CommissionEntry) *types.CommissionStatement {
+	statement := &types.CommissionStatement{
+		PartnerID: partnerID,
+		From:      from,
+		To:        to,
+		Entries:   entries,
+	}
+	for _, e := range entries {
+		statement.Earned += e.Amount
+		if e.Paid {
+			statement.Paid += e.Amount
+		}
+	}
+	statement.Earned = roundCents(statement.Earned)
+	statement.Paid = roundCents(statement.Paid)
+	statement.Unpaid = roundCents(statement.Earned - statement.Paid)
+	return statement
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// partnerOf returns the active partner a user belongs to
+func (s *PartnersService) partnerOf(ctx context.Context, orgID, userID uuid.UUID) (*types.Partner, error) {
+	partner, err := s.repo.PartnerForUser(ctx, orgID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotPartner
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !partner.Active {
+		return nil, ErrNotPartner
+	}
+	return partner, nil
+}
+
+// conflicts returns the open leads matching a deal's email, its email's
+// company domain or its company name
+func (s *PartnersService) conflicts(ctx context.Context, orgID uuid.UUID, email, companyName string) ([]types.ConflictingLead, error) {
+	return s.repo.FindConflicts(ctx, ConflictQuery(orgID, email, companyName))
+}
+
+// ConflictQuery builds what a deal is matched against open leads on. Free
+// mail domains are left out, since unrelated customers share them.
+func ConflictQuery(orgID uuid.UUID, email, companyName string) types.ConflictQuery {
+	query := types.ConflictQuery{
+		OrganizationID: orgID,
+		Email:          strings.ToLower(strings.TrimSpace(email)),
+		CompanyName:    strings.TrimSpace(companyName),
+	}
+	if at := strings.LastIndex(query.Email, "@"); at >= 0 {
+		if domain := query.Email[at+1:]; !freeMailDomains[domain] {
+			query.Domain = domain
+		}
+	}
+	return query
+}
+
+func conflictIDs(leads []types.ConflictingLead) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(leads))
+	for _, l := range leads {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// forPartner hides which leads a registration conflicts with, since they
+// may belong to other partners or the organization's own reps
+func forPartner(reg *types.DealRegistration) *types.DealRegistration {
+	reg.ConflictLeadIDs = nil
+	return reg
+}
+
+func normalizeRegistration(req *types.DealRegistrationRequest) error {
+	req.CompanyName = strings.TrimSpace(req.CompanyName)
+	req.ContactName = strings.TrimSpace(req.ContactName)
+	req.Email = strings.TrimSpace(req.Email)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.CompanyName == "" {
+		return fmt.Errorf("%w: company_name is required", ErrInvalid)
+	}
+	if req.Email == "" && req.Phone == "" {
+		return fmt.Errorf("%w: an email or phone is required", ErrInvalid)
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return fmt.Errorf("%w: email %q is not a valid address", ErrInvalid, req.Email)
+		}
+	}
+	if req.ExpectedRevenue != nil && *req.ExpectedRevenue < 0 {
+		return fmt.Errorf("%w: expected_revenue cannot be negative", ErrInvalid)
+	}
+	if len(req.Description) > maxDescription {
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalid, maxDescription)
+	}
+	return nil
+}
+
+func normalizeRegistrationFilter(filter *types.RegistrationFilter) error {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return nil
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// decided publishes a deal registration's decision and emails it to the
+// partner
+func (s *PartnersService) decided(ctx context.Context, eventType string, reg *types.DealRegistration) {
+	s.publish(ctx, eventType, reg)
+
+	if s.mailer == nil {
+		return
+	}
+	partner, err := s.repo.FindPartner(ctx, reg.OrganizationID, reg.PartnerID)
+	if err != nil {
+		s.logger.Warn("Failed to find partner to notify", "registration_id", reg.ID, "error", err)
+		return
+	}
+	if partner.Email == "" {
+		return
+	}
+
+	subject := fmt.Sprintf("Deal registration %s: %s", reg.Status, reg.CompanyName)
+	body := fmt.Sprintf("Your deal registration for %s was %s.", reg.CompanyName, reg.Status)
+	if reg.DecisionNote != "" {
+		body += "\n\n" + reg.DecisionNote
+	}
+
+	sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, reg.OrganizationID), sendTimeout)
+	defer cancel()
+	if err := s.mailer.Send(sendCtx, partner.Email, subject, body); err != nil {
+		s.logger.Warn("Failed to email deal registration decision", "registration_id", reg.ID, "error", err)
+	}
+}
+
+func (s *PartnersService) publish(ctx context.Context, eventType string, reg *types.DealRegistration) {
+	if s.eventBus == nil {
+		return
+	}
+	conflicts := reg.ConflictLeadIDs
+	if conflicts == nil {
+		conflicts = []uuid.UUID{}
+	}
+	event := types.RegistrationEvent{
+		OrganizationID:  reg.OrganizationID,
+		RegistrationID:  reg.ID,
+		PartnerID:       reg.PartnerID,
+		Status:          reg.Status,
+		CompanyName:     reg.CompanyName,
+		LeadID:          reg.LeadID,
+		ConflictLeadIDs: conflicts,
+	}
+	if err := s.eventBus.Publish(ctx, eventType, event); err != nil {
+		s.logger.Warn("Failed to publish deal registration", "event", eventType, "registration_id", reg.ID, "error", err)
+	}
+}
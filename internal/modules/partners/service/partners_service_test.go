@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/partners/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePartnersRepo struct {
+	partners      []types.Partner
+	members       map[uuid.UUID]uuid.UUID
+	conflicts     []types.ConflictingLead
+	conflictQuery types.ConflictQuery
+	registrations []types.DealRegistration
+	leads         []uuid.UUID
+}
+
+func newFakePartnersRepo() *fakePartnersRepo {
+	return &fakePartnersRepo{members: make(map[uuid.UUID]uuid.UUID)}
+}
+
+func (f *fakePartnersRepo) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.Partner, error) {
+	return f.partners, nil
+}
+
+func (f *fakePartnersRepo) FindPartner(ctx context.Context, orgID, id uuid.UUID) (*types.Partner, error) {
+	for i := range f.partners {
+		if f.partners[i].ID == id {
+			p := f.partners[i]
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("partner %w", repository.ErrNotFound)
+}
+
+func (f *fakePartnersRepo) CreatePartner(ctx context.Context, orgID, userID uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	partner := types.Partner{ID: uuid.New(), OrganizationID: orgID, Name: request.Name, Email: request.Email, Active: true}
+	f.partners = append(f.partners, partner)
+	return &partner, nil
+}
+
+func (f *fakePartnersRepo) UpdatePartner(ctx context.Context, orgID, userID, id uuid.UUID, request types.PartnerRequest) (*types.Partner, error) {
+	return f.FindPartner(ctx, orgID, id)
+}
+
+func (f *fakePartnersRepo) DeletePartner(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakePartnersRepo) AddPartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error {
+	f.members[userID] = partnerID
+	return nil
+}
+
+func (f *fakePartnersRepo) RemovePartnerUser(ctx context.Context, orgID, partnerID, userID uuid.UUID) error {
+	delete(f.members, userID)
+	return nil
+}
+
+func (f *fakePartnersRepo) PartnerForUser(ctx context.Context, orgID, userID uuid.UUID) (*types.Partner, error) {
+	partnerID, ok := f.members[userID]
+	if !ok {
+		return nil, fmt.Errorf("partner %w", repository.ErrNotFound)
+	}
+	return f.FindPartner(ctx, orgID, partnerID)
+}
+
+func (f *fakePartnersRepo) FindConflicts(ctx context.Context, query types.ConflictQuery) ([]types.ConflictingLead, error) {
+	f.conflictQuery = query
+	return f.conflicts, nil
+}
+
+func (f *fakePartnersRepo) CreateRegistration(ctx context.Context, registration types.DealRegistration) (*types.DealRegistration, error) {
+	registration.ID = uuid.New()
+	registration.Status = types.RegistrationPending
+	registration.HasConflict = len(registration.ConflictLeadIDs) > 0
+	f.registrations = append(f.registrations, registration)
+	return &registration, nil
+}
+
+func (f *fakePartnersRepo) FindRegistration(ctx context.Context, orgID, id uuid.UUID) (*types.DealRegistration, error) {
+	for i := range f.registrations {
+		if f.registrations[i].ID == id {
+			reg := f.registrations[i]
+			reg.ConflictLeadIDs = append([]uuid.UUID(nil), reg.ConflictLeadIDs...)
+			return &reg, nil
+		}
+	}
+	return nil, fmt.Errorf("deal registration %w", repository.ErrNotFound)
+}
+
+func (f *fakePartnersRepo) ListRegistrations(ctx context.Context, filter types.RegistrationFilter) ([]types.DealRegistration, error) {
+	var registrations []types.DealRegistration
+	for _, reg := range f.registrations {
+		if filter.PartnerID == nil || reg.PartnerID == *filter.PartnerID {
+			reg.ConflictLeadIDs = append([]uuid.UUID(nil), reg.ConflictLeadIDs...)
+			registrations = append(registrations, reg)
+		}
+	}
+	return registrations, nil
+}
+
+func (f *fakePartnersRepo) decide(id, userID uuid.UUID, status types.RegistrationStatus, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error) {
+	for i := range f.registrations {
+		reg := &f.registrations[i]
+		if reg.ID != id {
+			continue
+		}
+		if reg.Status != types.RegistrationPending {
+			return nil, repository.ErrDecided
+		}
+		reg.Status, reg.DecisionNote, reg.ConflictLeadIDs = status, note, conflicts
+		reg.HasConflict = len(conflicts) > 0
+		reg.DecidedBy, reg.DecidedAt = &userID, &at
+		if status == types.RegistrationApproved {
+			leadID := uuid.New()
+			f.leads = append(f.leads, leadID)
+			reg.LeadID = &leadID
+		}
+		decided := *reg
+		return &decided, nil
+	}
+	return nil, fmt.Errorf("deal registration %w", repository.ErrNotFound)
+}
+
+func (f *fakePartnersRepo) ApproveRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error) {
+	return f.decide(id, userID, types.RegistrationApproved, note, conflicts, at)
+}
+
+func (f *fakePartnersRepo) RejectRegistration(ctx context.Context, orgID, id, userID uuid.UUID, note string, conflicts []uuid.UUID, at time.Time) (*types.DealRegistration, error) {
+	return f.decide(id, userID, types.RegistrationRejected, note, conflicts, at)
+}
+
+func (f *fakePartnersRepo) Pipeline(ctx context.Context, filter types.PipelineFilter) ([]types.PipelineLead, error) {
+	return nil, nil
+}
+
+func (f *fakePartnersRepo) CommissionEntries(ctx context.Context, orgID, partnerID uuid.UUID, from, to time.Time) ([]types.CommissionEntry, error) {
+	return nil, nil
+}
+
+type sentMessage struct {
+	to, subject, body string
+}
+
+type fakeMailer struct {
+	sent []sentMessage
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, sentMessage{to: to, subject: subject, body: body})
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error {
+	return nil
+}
+
+var testNow = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func newTestService(repo *fakePartnersRepo) *PartnersService {
+	s := NewPartnersService(repo, allowAll{}, nil, nil)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// withPartner adds an active partner with one user to repo
+func withPartner(repo *fakePartnersRepo, name string) (types.Partner, uuid.UUID) {
+	partner := types.Partner{ID: uuid.New(), Name: name, Email: "deals@" + name + ".test", Active: true}
+	repo.partners = append(repo.partners, partner)
+	userID := uuid.New()
+	repo.members[userID] = partner.ID
+	return partner, userID
+}
+
+func TestConflictQuerySkipsFreeMailDomains(t *testing.T) {
+	orgID := uuid.New()
+
+	query := ConflictQuery(orgID, " Ada@Acme.Example ", " Acme Corp ")
+	assert.Equal(t, "ada@acme.example", query.Email)
+	assert.Equal(t, "acme.example", query.Domain)
+	assert.Equal(t, "Acme Corp", query.CompanyName)
+
+	query = ConflictQuery(orgID, "ada@gmail.com", "Acme Corp")
+	assert.Equal(t, "ada@gmail.com", query.Email)
+	assert.Empty(t, query.Domain)
+}
+
+func TestRegisterDealRequiresActivePartner(t *testing.T) {
+	repo := newFakePartnersRepo()
+	s := newTestService(repo)
+	orgID := uuid.New()
+	req := types.DealRegistrationRequest{CompanyName: "Acme", Email: "ada@acme.example"}
+
+	_, err := s.RegisterDeal(context.Background(), orgID, uuid.New(), req)
+	assert.ErrorIs(t, err, ErrNotPartner)
+
+	_, userID := withPartner(repo, "resell")
+	repo.partners[0].Active = false
+	_, err = s.RegisterDeal(context.Background(), orgID, userID, req)
+	assert.ErrorIs(t, err, ErrNotPartner)
+
+	repo.partners[0].Active = true
+	_, err = s.RegisterDeal(context.Background(), orgID, userID, types.DealRegistrationRequest{CompanyName: "Acme"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestRegisterDealRecordsConflictsHiddenFromPartner(t *testing.T) {
+	repo := newFakePartnersRepo()
+	partner, userID := withPartner(repo, "resell")
+	conflict := types.ConflictingLead{ID: uuid.New(), Name: "Acme", Status: "in_progress"}
+	repo.conflicts = []types.ConflictingLead{conflict}
+	s := newTestService(repo)
+	orgID := uuid.New()
+
+	reg, err := s.RegisterDeal(context.Background(), orgID, userID, types.DealRegistrationRequest{
+		CompanyName: " Acme ", ContactName: "Ada", Email: "ada@acme.example",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, partner.ID, reg.PartnerID)
+	assert.Equal(t, userID, reg.RegisteredBy)
+	assert.Equal(t, "Acme", reg.CompanyName)
+	assert.True(t, reg.HasConflict)
+	assert.Empty(t, reg.ConflictLeadIDs)
+	assert.Equal(t, "acme.example", repo.conflictQuery.Domain)
+
+	// Staff see which leads it conflicts with
+	stored, err := s.GetRegistration(context.Background(), orgID, reg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{conflict.ID}, stored.ConflictLeadIDs)
+
+	mine, err := s.MyRegistrations(context.Background(), orgID, userID, types.RegistrationFilter{})
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Empty(t, mine[0].ConflictLeadIDs)
+}
+
+func TestMyRegistrationHidesOtherPartners(t *testing.T) {
+	repo := newFakePartnersRepo()
+	_, userID := withPartner(repo, "resell")
+	_, otherUserID := withPartner(repo, "refer")
+	s := newTestService(repo)
+	orgID := uuid.New()
+
+	reg, err := s.RegisterDeal(context.Background(), orgID, userID, types.DealRegistrationRequest{
+		CompanyName: "Acme", Phone: "+15550100",
+	})
+	require.NoError(t, err)
+
+	_, err = s.MyRegistration(context.Background(), orgID, otherUserID, reg.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	mine, err := s.MyRegistrations(context.Background(), orgID, otherUserID, types.RegistrationFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, mine)
+}
+
+func TestApproveRegistrationRefusesConflictsWithoutOverride(t *testing.T) {
+	repo := newFakePartnersRepo()
+	partner, userID := withPartner(repo, "resell")
+	mailer := &fakeMailer{}
+	s := newTestService(repo)
+	s.SetMailer(mailer)
+	orgID, staffID := uuid.New(), uuid.New()
+
+	reg, err := s.RegisterDeal(context.Background(), orgID, userID, types.DealRegistrationRequest{
+		CompanyName: "Acme", Email: "ada@acme.example",
+	})
+	require.NoError(t, err)
+	assert.False(t, reg.HasConflict)
+
+	// A rep opened a lead for the same company after the deal was registered
+	conflict := types.ConflictingLead{ID: uuid.New(), Name: "Acme", Status: "new"}
+	repo.conflicts = []types.ConflictingLead{conflict}
+
+	_, err = s.ApproveRegistration(context.Background(), orgID, staffID, reg.ID, types.DecisionRequest{})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Empty(t, repo.leads)
+	assert.Empty(t, mailer.sent)
+
+	approved, err := s.ApproveRegistration(context.Background(), orgID, staffID, reg.ID, types.DecisionRequest{
+		Note: "Partner sourced it first", Override: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.RegistrationApproved, approved.Status)
+	require.NotNil(t, approved.LeadID)
+	assert.Equal(t, []uuid.UUID{*approved.LeadID}, repo.leads)
+	assert.Equal(t, []uuid.UUID{conflict.ID}, approved.ConflictLeadIDs)
+	assert.Equal(t, &testNow, approved.DecidedAt)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, partner.Email, mailer.sent[0].to)
+	assert.Contains(t, mailer.sent[0].subject, "approved")
+	assert.Contains(t, mailer.sent[0].body, "Partner sourced it first")
+
+	_, err = s.RejectRegistration(context.Background(), orgID, staffID, reg.ID, types.DecisionRequest{})
+	assert.ErrorIs(t, err, repository.ErrDecided)
+}
+
+func TestRejectRegistrationCreatesNoLead(t *testing.T) {
+	repo := newFakePartnersRepo()
+	_, userID := withPartner(repo, "resell")
+	s := newTestService(repo)
+	orgID := uuid.New()
+
+	reg, err := s.RegisterDeal(context.Background(), orgID, userID, types.DealRegistrationRequest{
+		CompanyName: "Acme", Email: "ada@acme.example",
+	})
+	require.NoError(t, err)
+
+	rejected, err := s.RejectRegistration(context.Background(), orgID, uuid.New(), reg.ID, types.DecisionRequest{Note: " Existing customer "})
+	require.NoError(t, err)
+	assert.Equal(t, types.RegistrationRejected, rejected.Status)
+	assert.Equal(t, "Existing customer", rejected.DecisionNote)
+	assert.Nil(t, rejected.LeadID)
+	assert.Empty(t, repo.leads)
+}
+
+func TestMyCommissionStatementValidatesPeriod(t *testing.T) {
+	repo := newFakePartnersRepo()
+	_, userID := withPartner(repo, "resell")
+	s := newTestService(repo)
+	orgID := uuid.New()
+
+	statement, err := s.MyCommissionStatement(context.Background(), orgID, userID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), statement.From)
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), statement.To)
+
+	_, err = s.MyCommissionStatement(context.Background(), orgID, userID, testNow, testNow.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = s.MyCommissionStatement(context.Background(), orgID, userID, testNow.AddDate(-2, 0, 0), testNow)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestBuildStatementTotals(t *testing.T) {
+	partnerID := uuid.New()
+	from, to := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	statement := BuildStatement(partnerID, from, to, []types.CommissionEntry{
+		{EntryType: "accrual", Amount: 120.10, Paid: true},
+		{EntryType: "accrual", Amount: 80.20},
+		{EntryType: "adjustment", Amount: -10.05},
+	})
+	assert.Equal(t, partnerID, statement.PartnerID)
+	assert.Equal(t, 190.25, statement.Earned)
+	assert.Equal(t, 120.10, statement.Paid)
+	assert.Equal(t, 70.15, statement.Unpaid)
+	assert.Len(t, statement.Entries, 3)
+}
@@ -0,0 +1,185 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RolePartner is the role of partner users, who only reach the partner API
+const RolePartner = "partner"
+
+// Partner is a reseller or referral company registering deals with the
+// organization
+type Partner struct {
+	ID             uuid.UUID   `json:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	Name           string      `json:"name"`
+	Email          string      `json:"email"`
+	Active         bool        `json:"active"`
+	UserIDs        []uuid.UUID `json:"user_ids"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	CreatedBy      *uuid.UUID  `json:"created_by,omitempty"`
+	UpdatedBy      *uuid.UUID  `json:"updated_by,omitempty"`
+}
+
+// PartnerRequest creates or updates a partner. Email receives the decisions
+// on the partner's deal registrations.
+type PartnerRequest struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Active *bool  `json:"active,omitempty"`
+}
+
+// PartnerUserRequest adds a partner-role user to a partner
+type PartnerUserRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// RegistrationStatus is where a deal registration stands
+type RegistrationStatus string
+
+const (
+	RegistrationPending  RegistrationStatus = "pending"
+	RegistrationApproved RegistrationStatus = "approved"
+	RegistrationRejected RegistrationStatus = "rejected"
+)
+
+// IsValid reports whether the status is known
+func (s RegistrationStatus) IsValid() bool {
+	switch s {
+	case RegistrationPending, RegistrationApproved, RegistrationRejected:
+		return true
+	}
+	return false
+}
+
+// DealRegistration is a deal a partner registered. ConflictLeadIDs are the
+// open leads it matched when it was last checked; partners only see
+// whether there were any.
+type DealRegistration struct {
+	ID              uuid.UUID          `json:"id"`
+	OrganizationID  uuid.UUID          `json:"organization_id"`
+	PartnerID       uuid.UUID          `json:"partner_id"`
+	RegisteredBy    uuid.UUID          `json:"registered_by"`
+	CompanyName     string             `json:"company_name"`
+	ContactName     string             `json:"contact_name"`
+	Email           string             `json:"email"`
+	Phone           string             `json:"phone"`
+	ExpectedRevenue *float64           `json:"expected_revenue,omitempty"`
+	Description     string             `json:"description"`
+	Status          RegistrationStatus `json:"status"`
+	HasConflict     bool               `json:"has_conflict"`
+	ConflictLeadIDs []uuid.UUID        `json:"conflict_lead_ids,omitempty"`
+	LeadID          *uuid.UUID         `json:"lead_id,omitempty"`
+	DecisionNote    string             `json:"decision_note"`
+	DecidedBy       *uuid.UUID         `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time         `json:"decided_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+// DealRegistrationRequest registers a deal
+type DealRegistrationRequest struct {
+	CompanyName     string   `json:"company_name"`
+	ContactName     string   `json:"contact_name"`
+	Email           string   `json:"email"`
+	Phone           string   `json:"phone"`
+	ExpectedRevenue *float64 `json:"expected_revenue,omitempty"`
+	Description     string   `json:"description"`
+}
+
+// DecisionRequest approves or rejects a deal registration. A registration
+// conflicting with open leads is only approved with Override.
+type DecisionRequest struct {
+	Note     string `json:"note"`
+	Override bool   `json:"override"`
+}
+
+// RegistrationFilter narrows a list of deal registrations
+type RegistrationFilter struct {
+	OrganizationID uuid.UUID
+	PartnerID      *uuid.UUID
+	Status         RegistrationStatus
+	Limit          int
+	Offset         int
+}
+
+// ConflictQuery is what a deal registration is matched against open leads
+// on. An empty field matches nothing.
+type ConflictQuery struct {
+	OrganizationID uuid.UUID
+	Email          string
+	Domain         string
+	CompanyName    string
+}
+
+// ConflictingLead is an open lead a deal registration matched
+type ConflictingLead struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	ContactName string     `json:"contact_name"`
+	Email       string     `json:"email"`
+	Status      string     `json:"status"`
+	PartnerID   *uuid.UUID `json:"partner_id,omitempty"`
+	AssignedTo  *uuid.UUID `json:"assigned_to,omitempty"`
+}
+
+// PipelineLead is a lead attributed to a partner, as the partner sees it
+type PipelineLead struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	ContactName     string     `json:"contact_name"`
+	Email           string     `json:"email"`
+	Status          string     `json:"status"`
+	Stage           string     `json:"stage"`
+	ExpectedRevenue *float64   `json:"expected_revenue,omitempty"`
+	Probability     int        `json:"probability"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DateClosed      *time.Time `json:"date_closed,omitempty"`
+}
+
+// PipelineFilter narrows a partner's pipeline
+type PipelineFilter struct {
+	OrganizationID uuid.UUID
+	PartnerID      uuid.UUID
+	Status         string
+	Limit          int
+	Offset         int
+}
+
+// CommissionEntry is a commission earned by one of a partner's users
+type CommissionEntry struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	EntryType  string     `json:"entry_type"`
+	SourceType string     `json:"source_type,omitempty"`
+	SourceID   *uuid.UUID `json:"source_id,omitempty"`
+	SourceName string     `json:"source_name,omitempty"`
+	EarnedOn   time.Time  `json:"earned_on"`
+	Amount     float64    `json:"amount"`
+	Paid       bool       `json:"paid"`
+}
+
+// CommissionStatement is what a partner's users earned over a period
+type CommissionStatement struct {
+	PartnerID uuid.UUID         `json:"partner_id"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Entries   []CommissionEntry `json:"entries"`
+	Earned    float64           `json:"earned"`
+	Paid      float64           `json:"paid"`
+	Unpaid    float64           `json:"unpaid"`
+}
+
+// RegistrationEvent is published when a deal registration is submitted,
+// approved or rejected
+type RegistrationEvent struct {
+	OrganizationID  uuid.UUID          `json:"organization_id"`
+	RegistrationID  uuid.UUID          `json:"registration_id"`
+	PartnerID       uuid.UUID          `json:"partner_id"`
+	Status          RegistrationStatus `json:"status"`
+	CompanyName     string             `json:"company_name"`
+	LeadID          *uuid.UUID         `json:"lead_id,omitempty"`
+	ConflictLeadIDs []uuid.UUID        `json:"conflict_lead_ids"`
+}
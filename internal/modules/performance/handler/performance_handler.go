@@ -0,0 +1,698 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PerformanceHandler handles goals, their check-ins and attainment, review
+// templates, review cycles and reviews
+type PerformanceHandler struct {
+	service *service.PerformanceService
+}
+
+func NewPerformanceHandler(service *service.PerformanceService) *PerformanceHandler {
+	return &PerformanceHandler{service: service}
+}
+
+func (h *PerformanceHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/goals", h.ListGoals)
+	router.POST("/api/goals", h.CreateGoal)
+	router.GET("/api/goals/:id", h.GetGoal)
+	router.PUT("/api/goals/:id", h.UpdateGoal)
+	router.POST("/api/goals/:id/complete", h.CompleteGoal)
+	router.POST("/api/goals/:id/cancel", h.CancelGoal)
+	router.GET("/api/goals/:id/check-ins", h.ListCheckIns)
+	router.POST("/api/goals/:id/check-ins", h.CheckIn)
+	router.GET("/api/goal-attainment", h.Attainment)
+
+	router.GET("/api/review-templates", h.ListTemplates)
+	router.POST("/api/review-templates", h.CreateTemplate)
+	router.GET("/api/review-templates/:id", h.GetTemplate)
+	router.PUT("/api/review-templates/:id", h.UpdateTemplate)
+
+	router.GET("/api/review-cycles", h.ListCycles)
+	router.POST("/api/review-cycles", h.CreateCycle)
+	router.GET("/api/review-cycles/:id", h.GetCycle)
+	router.PUT("/api/review-cycles/:id", h.UpdateCycle)
+	router.POST("/api/review-cycles/:id/open", h.OpenCycle)
+	router.POST("/api/review-cycles/:id/close", h.CloseCycle)
+	router.POST("/api/review-cycles/:id/peer-requests", h.RequestPeerReviews)
+	router.GET("/api/review-cycles/:id/summary/:employeeId", h.Summary)
+
+	router.GET("/api/reviews", h.ListReviews)
+	router.GET("/api/reviews/:id", h.GetReview)
+	router.POST("/api/reviews/:id/submit", h.SubmitReview)
+	router.GET("/api/my-reviews", h.MyReviews)
+}
+
+// goalFilter reads the goal filter of the query: employee_id,
+// department_id, state, and the from and to dates the goal periods overlap
+// or the fiscal period they overlap
+func goalFilter(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) (types.GoalFilter, bool) {
+	q := r.URL.Query()
+	filter := types.GoalFilter{OrganizationID: orgID, State: types.GoalState(q.Get("state")), Period: q.Get("period")}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{
+		"employee_id":   &filter.EmployeeID,
+		"department_id": &filter.DepartmentID,
+	}) {
+		return filter, false
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			day, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+", expected YYYY-MM-DD", http.StatusBadRequest)
+				return filter, false
+			}
+			*dest = &day
+		}
+	}
+	return filter, true
+}
+
+// ListGoals handles GET /api/goals
+func (h *PerformanceHandler) ListGoals(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := goalFilter(w, r, authCtx.OrganizationID)
+	if !ok {
+		return
+	}
+
+	goals, err := h.service.ListGoals(r.Context(), filter)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goals)
+}
+
+// CreateGoal handles POST /api/goals
+func (h *PerformanceHandler) CreateGoal(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.CreateGoal(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, goal)
+}
+
+// GetGoal handles GET /api/goals/:id
+func (h *PerformanceHandler) GetGoal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.GetGoal(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+}
+
+// UpdateGoal handles PUT /api/goals/:id
+func (h *PerformanceHandler) UpdateGoal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.GoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.UpdateGoal(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+}
+
+// CompleteGoal handles POST /api/goals/:id/complete
+func (h *PerformanceHandler) CompleteGoal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.CompleteGoal(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+}
+
+// CancelGoal handles POST /api/goals/:id/cancel
+func (h *PerformanceHandler) CancelGoal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.CancelGoal(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+}
+
+// ListCheckIns handles GET /api/goals/:id/check-ins
+func (h *PerformanceHandler) ListCheckIns(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	checkIns, err := h.service.ListCheckIns(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, checkIns)
+}
+
+// CheckIn handles POST /api/goals/:id/check-ins
+func (h *PerformanceHandler) CheckIn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	checkIn, err := h.service.CheckIn(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, checkIn)
+}
+
+// Attainment handles GET /api/goal-attainment, reporting the sales key
+// results of the goals selected like GET /api/goals against their targets
+func (h *PerformanceHandler) Attainment(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := goalFilter(w, r, authCtx.OrganizationID)
+	if !ok {
+		return
+	}
+
+	attainment, err := h.service.Attainment(r.Context(), filter)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attainment)
+}
+
+// ListTemplates handles GET /api/review-templates
+func (h *PerformanceHandler) ListTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	templates, err := h.service.ListTemplates(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// CreateTemplate handles POST /api/review-templates
+func (h *PerformanceHandler) CreateTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ReviewTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.CreateTemplate(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplate handles GET /api/review-templates/:id
+func (h *PerformanceHandler) GetTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.GetTemplate(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// UpdateTemplate handles PUT /api/review-templates/:id
+func (h *PerformanceHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ReviewTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// ListCycles handles GET /api/review-cycles
+func (h *PerformanceHandler) ListCycles(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	cycles, err := h.service.ListCycles(r.Context(), authCtx.OrganizationID, types.CycleState(r.URL.Query().Get("state")))
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cycles)
+}
+
+// CreateCycle handles POST /api/review-cycles
+func (h *PerformanceHandler) CreateCycle(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ReviewCycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cycle, err := h.service.CreateCycle(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cycle)
+}
+
+// GetCycle handles GET /api/review-cycles/:id
+func (h *PerformanceHandler) GetCycle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+
+	cycle, err := h.service.GetCycle(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cycle)
+}
+
+// UpdateCycle handles PUT /api/review-cycles/:id
+func (h *PerformanceHandler) UpdateCycle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ReviewCycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cycle, err := h.service.UpdateCycle(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cycle)
+}
+
+// OpenCycle handles POST /api/review-cycles/:id/open
+func (h *PerformanceHandler) OpenCycle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+
+	cycle, err := h.service.OpenCycle(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cycle)
+}
+
+// CloseCycle handles POST /api/review-cycles/:id/close
+func (h *PerformanceHandler) CloseCycle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+
+	cycle, err := h.service.CloseCycle(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cycle)
+}
+
+// RequestPeerReviews handles POST /api/review-cycles/:id/peer-requests
+func (h *PerformanceHandler) RequestPeerReviews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.PeerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.RequestPeerReviews(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"requested": created})
+}
+
+// Summary handles GET /api/review-cycles/:id/summary/:employeeId
+func (h *PerformanceHandler) Summary(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid cycle ID", http.StatusBadRequest)
+		return
+	}
+	employeeID, err := uuid.Parse(ps.ByName("employeeId"))
+	if err != nil {
+		http.Error(w, "Invalid employee ID", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.Summary(r.Context(), authCtx.OrganizationID, id, employeeID)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// ListReviews handles GET /api/reviews
+func (h *PerformanceHandler) ListReviews(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.ReviewFilter{
+		OrganizationID: authCtx.OrganizationID,
+		State:          types.ReviewState(r.URL.Query().Get("state")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{
+		"cycle_id":         &filter.CycleID,
+		"employee_id":      &filter.EmployeeID,
+		"reviewer_user_id": &filter.ReviewerUserID,
+	}) {
+		return
+	}
+
+	reviews, err := h.service.ListReviews(r.Context(), filter)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reviews)
+}
+
+// MyReviews handles GET /api/my-reviews, listing the reviews the user is
+// asked to write
+func (h *PerformanceHandler) MyReviews(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	reviews, err := h.service.MyReviews(r.Context(), authCtx.OrganizationID, authCtx.UserID,
+		types.ReviewState(r.URL.Query().Get("state")))
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reviews)
+}
+
+// GetReview handles GET /api/reviews/:id
+func (h *PerformanceHandler) GetReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.service.GetReview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, review)
+}
+
+// SubmitReview handles POST /api/reviews/:id/submit
+func (h *PerformanceHandler) SubmitReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ReviewSubmission
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.service.SubmitReview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePerformanceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, review)
+}
+
+func queryUUIDs(w http.ResponseWriter, r *http.Request, params map[string]**uuid.UUID) bool {
+	q := r.URL.Query()
+	for name, dest := range params {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return false
+			}
+			*dest = &id
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writePerformanceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotReviewer):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
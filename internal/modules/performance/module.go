@@ -0,0 +1,77 @@
+package performance
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PerformanceModule represents the performance module: goals with key
+// results and check-ins, sales quota attainment and review cycles
+type PerformanceModule struct {
+	performanceService *service.PerformanceService
+	performanceHandler *handler.PerformanceHandler
+	logger             *slog.Logger
+}
+
+// NewPerformanceModule creates a new performance module
+func NewPerformanceModule() *PerformanceModule {
+	return &PerformanceModule{}
+}
+
+// Name returns the module name
+func (m *PerformanceModule) Name() string {
+	return "performance"
+}
+
+// Init initializes the performance module
+func (m *PerformanceModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "performance")
+	m.logger.Info("Initializing performance module")
+
+	// Create repositories
+	performanceRepo := repository.NewPerformanceRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.performanceService = service.NewPerformanceService(performanceRepo, authAdapter, deps.EventBus, m.logger)
+	m.performanceService.SetFiscalPeriods(fiscal.NewPeriods(fiscal.NewStore(deps.DB)))
+
+	// Create handlers
+	m.performanceHandler = handler.NewPerformanceHandler(m.performanceService)
+
+	m.logger.Info("Performance module initialized successfully")
+	return nil
+}
+
+// PerformanceService returns the performance service
+func (m *PerformanceModule) PerformanceService() *service.PerformanceService {
+	return m.performanceService
+}
+
+// RegisterRoutes registers performance module routes
+func (m *PerformanceModule) RegisterRoutes(router interface{}) {
+	if m.performanceHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.performanceHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the performance module
+func (m *PerformanceModule) RegisterEventHandlers(bus interface{}) {
+	// Performance only publishes events; sales actuals are read when goals are measured
+}
+
+// Health checks the health of the performance module
+func (m *PerformanceModule) Health() error {
+	return nil
+}
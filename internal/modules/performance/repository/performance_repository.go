@@ -0,0 +1,993 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a goal, key result, template, cycle,
+	// review, employee or department does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a review template name is already used
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when the goal, cycle or review state does
+	// not allow the change
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// PerformanceRepo defines the interface for performance repository operations
+type PerformanceRepo interface {
+	ListGoals(ctx context.Context, filter types.GoalFilter) ([]types.Goal, error)
+	FindGoal(ctx context.Context, orgID, id uuid.UUID) (*types.Goal, error)
+	CreateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error)
+	UpdateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error)
+	SetGoalState(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, state types.GoalState) (*types.Goal, error)
+	CheckIn(ctx context.Context, checkIn types.CheckIn) (*types.CheckIn, error)
+	ListCheckIns(ctx context.Context, orgID, goalID uuid.UUID) ([]types.CheckIn, error)
+	OwnerUserIDs(ctx context.Context, orgID uuid.UUID, employeeID, departmentID *uuid.UUID) ([]uuid.UUID, error)
+	SalesActual(ctx context.Context, orgID uuid.UUID, metric types.Metric, userIDs []uuid.UUID, from, to time.Time) (float64, error)
+	ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.ReviewTemplate, error)
+	FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewTemplate, error)
+	CreateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error)
+	UpdateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error)
+	ListCycles(ctx context.Context, orgID uuid.UUID, state types.CycleState) ([]types.ReviewCycle, error)
+	FindCycle(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewCycle, error)
+	CreateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error)
+	UpdateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error)
+	OpenCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reviews []types.Review) (*types.ReviewCycle, error)
+	CloseCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID) (*types.ReviewCycle, error)
+	ListEmployees(ctx context.Context, orgID uuid.UUID, departmentID *uuid.UUID) ([]types.Employee, error)
+	FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error)
+	AddReviews(ctx context.Context, reviews []types.Review) (int, error)
+	ListReviews(ctx context.Context, filter types.ReviewFilter) ([]types.Review, error)
+	FindReview(ctx context.Context, orgID, id uuid.UUID) (*types.Review, error)
+	SubmitReview(ctx context.Context, orgID, id uuid.UUID, answers []types.Answer, submittedAt time.Time) (*types.Review, error)
+}
+
+// PerformanceRepository stores goals with their key results and check-ins,
+// review templates, review cycles and reviews
+type PerformanceRepository struct {
+	db *sql.DB
+}
+
+// Ensure PerformanceRepository implements PerformanceRepo interface
+var _ PerformanceRepo = &PerformanceRepository{}
+
+func NewPerformanceRepository(db *sql.DB) *PerformanceRepository {
+	return &PerformanceRepository{db: db}
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+func checkExists(ctx context.Context, q queryer, what, query string, args ...interface{}) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (`+query+`)`, args...).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check %s: %w", what, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %w", what, ErrNotFound)
+	}
+	return nil
+}
+
+func checkDepartment(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	return checkExists(ctx, q, "department",
+		`SELECT 1 FROM departments WHERE id = $1 AND organization_id = $2`, *id, orgID)
+}
+
+func checkEmployee(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	return checkExists(ctx, q, "employee",
+		`SELECT 1 FROM employees WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL`, *id, orgID)
+}
+
+const goalColumns = `id, organization_id, employee_id, department_id, parent_id, title, COALESCE(description, ''),
+	period_start, period_end, state, created_at, updated_at, created_by`
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (*types.Goal, error) {
+	var g types.Goal
+	var employeeID, departmentID, parentID, createdBy uuid.NullUUID
+	if err := row.Scan(&g.ID, &g.OrganizationID, &employeeID, &departmentID, &parentID, &g.Title, &g.Description,
+		&g.PeriodStart, &g.PeriodEnd, &g.State, &g.CreatedAt, &g.UpdatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	g.EmployeeID = nullUUID(employeeID)
+	g.DepartmentID = nullUUID(departmentID)
+	g.ParentID = nullUUID(parentID)
+	g.CreatedBy = nullUUID(createdBy)
+	g.KeyResults = []types.KeyResult{}
+	return &g, nil
+}
+
+// withKeyResults loads the key results of the goals, in sequence order
+func (r *PerformanceRepository) withKeyResults(ctx context.Context, goals []types.Goal) error {
+	if len(goals) == 0 {
+		return nil
+	}
+	index := make(map[uuid.UUID]int, len(goals))
+	ids := make([]uuid.UUID, len(goals))
+	for i, g := range goals {
+		index[g.ID] = i
+		ids[i] = g.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, goal_id, title, metric, COALESCE(unit, ''), start_value, target_value, current_value,
+			sequence, updated_at
+		FROM goal_key_results
+		WHERE goal_id = ANY($1::uuid[])
+		ORDER BY sequence, created_at, id
+	`, pq.Array(uuidStrings(ids)))
+	if err != nil {
+		return fmt.Errorf("failed to list key results: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kr types.KeyResult
+		if err := rows.Scan(&kr.ID, &kr.GoalID, &kr.Title, &kr.Metric, &kr.Unit, &kr.StartValue, &kr.TargetValue,
+			&kr.CurrentValue, &kr.Sequence, &kr.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan key result: %w", err)
+		}
+		g := &goals[index[kr.GoalID]]
+		g.KeyResults = append(g.KeyResults, kr)
+	}
+	return rows.Err()
+}
+
+func (r *PerformanceRepository) ListGoals(ctx context.Context, filter types.GoalFilter) ([]types.Goal, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.EmployeeID != nil {
+		args = append(args, *filter.EmployeeID)
+		where = append(where, fmt.Sprintf("employee_id = $%d", len(args)))
+	}
+	if filter.DepartmentID != nil {
+		args = append(args, *filter.DepartmentID)
+		where = append(where, fmt.Sprintf("department_id = $%d", len(args)))
+	}
+	if filter.State != "" {
+		args = append(args, string(filter.State))
+		where = append(where, fmt.Sprintf("state = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where = append(where, fmt.Sprintf("period_end >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where = append(where, fmt.Sprintf("period_start <= $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+goalColumns+` FROM goals
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY period_start DESC, title, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []types.Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := r.withKeyResults(ctx, goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *PerformanceRepository) FindGoal(ctx context.Context, orgID, id uuid.UUID) (*types.Goal, error) {
+	g, err := scanGoal(r.db.QueryRowContext(ctx, `
+		SELECT `+goalColumns+` FROM goals WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("goal %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find goal: %w", err)
+	}
+	goals := []types.Goal{*g}
+	if err := r.withKeyResults(ctx, goals); err != nil {
+		return nil, err
+	}
+	return &goals[0], nil
+}
+
+func checkGoalReferences(ctx context.Context, q queryer, goal types.Goal) error {
+	if err := checkEmployee(ctx, q, goal.OrganizationID, goal.EmployeeID); err != nil {
+		return err
+	}
+	if err := checkDepartment(ctx, q, goal.OrganizationID, goal.DepartmentID); err != nil {
+		return err
+	}
+	if goal.ParentID == nil {
+		return nil
+	}
+	return checkExists(ctx, q, "parent goal",
+		`SELECT 1 FROM goals WHERE id = $1 AND organization_id = $2`, *goal.ParentID, goal.OrganizationID)
+}
+
+func insertKeyResult(ctx context.Context, tx execer, orgID, goalID uuid.UUID, kr types.KeyResult) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO goal_key_results (
+			id, organization_id, goal_id, title, metric, unit, start_value, target_value, current_value, sequence
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $7, $9)
+	`, uuid.New(), orgID, goalID, kr.Title, string(kr.Metric), kr.Unit, kr.StartValue, kr.TargetValue,
+		kr.Sequence); err != nil {
+		return fmt.Errorf("failed to create key result: %w", err)
+	}
+	return nil
+}
+
+// CreateGoal creates a goal with its key results. Key results start at
+// their start value.
+func (r *PerformanceRepository) CreateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkGoalReferences(ctx, tx, goal); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO goals (
+			id, organization_id, employee_id, department_id, parent_id, title, description,
+			period_start, period_end, state, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $11)
+	`, id, goal.OrganizationID, goal.EmployeeID, goal.DepartmentID, goal.ParentID, goal.Title, goal.Description,
+		goal.PeriodStart, goal.PeriodEnd, string(goal.State), userID); err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+
+	for _, kr := range goal.KeyResults {
+		if err := insertKeyResult(ctx, tx, goal.OrganizationID, id, kr); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit goal: %w", err)
+	}
+	return r.FindGoal(ctx, goal.OrganizationID, id)
+}
+
+// UpdateGoal replaces an active goal and its key results. Key results with
+// an ID are kept with their current value, those without are created and
+// those left out are deleted with their check-ins.
+func (r *PerformanceRepository) UpdateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state types.GoalState
+	err = tx.QueryRowContext(ctx, `
+		SELECT state FROM goals WHERE organization_id = $1 AND id = $2 FOR UPDATE
+	`, goal.OrganizationID, goal.ID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("goal %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock goal: %w", err)
+	}
+	if state != types.GoalActive {
+		return nil, fmt.Errorf("goal is %s: %w", state, ErrInvalidState)
+	}
+	if goal.ParentID != nil && *goal.ParentID == goal.ID {
+		return nil, fmt.Errorf("goal cannot contribute to itself: %w", ErrInvalidState)
+	}
+	if err := checkGoalReferences(ctx, tx, goal); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE goals SET employee_id = $3, department_id = $4, parent_id = $5, title = $6, description = NULLIF($7, ''),
+			period_start = $8, period_end = $9, updated_by = $10, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+	`, goal.OrganizationID, goal.ID, goal.EmployeeID, goal.DepartmentID, goal.ParentID, goal.Title, goal.Description,
+		goal.PeriodStart, goal.PeriodEnd, userID); err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	kept := []uuid.UUID{}
+	for _, kr := range goal.KeyResults {
+		if kr.ID != uuid.Nil {
+			kept = append(kept, kr.ID)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM goal_key_results WHERE goal_id = $1 AND NOT (id = ANY($2::uuid[]))
+	`, goal.ID, pq.Array(uuidStrings(kept))); err != nil {
+		return nil, fmt.Errorf("failed to delete key results: %w", err)
+	}
+
+	for _, kr := range goal.KeyResults {
+		if kr.ID == uuid.Nil {
+			if err := insertKeyResult(ctx, tx, goal.OrganizationID, goal.ID, kr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result, err := tx.ExecContext(ctx, `
+			UPDATE goal_key_results SET title = $3, metric = $4, unit = NULLIF($5, ''), start_value = $6,
+				target_value = $7, sequence = $8, updated_at = now()
+			WHERE goal_id = $1 AND id = $2
+		`, goal.ID, kr.ID, kr.Title, string(kr.Metric), kr.Unit, kr.StartValue, kr.TargetValue, kr.Sequence)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update key result: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("key result %s %w", kr.ID, ErrNotFound)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit goal: %w", err)
+	}
+	return r.FindGoal(ctx, goal.OrganizationID, goal.ID)
+}
+
+// SetGoalState completes or cancels an active goal
+func (r *PerformanceRepository) SetGoalState(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, state types.GoalState) (*types.Goal, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE goals SET state = $3, updated_by = $4, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'active'
+	`, orgID, id, string(state), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update goal state: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		goal, err := r.FindGoal(ctx, orgID, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("goal is %s: %w", goal.State, ErrInvalidState)
+	}
+	return r.FindGoal(ctx, orgID, id)
+}
+
+// CheckIn records a check-in and moves its key result to the checked-in
+// value. The goal must be active.
+func (r *PerformanceRepository) CheckIn(ctx context.Context, checkIn types.CheckIn) (*types.CheckIn, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state types.GoalState
+	err = tx.QueryRowContext(ctx, `
+		SELECT g.state FROM goal_key_results kr
+		JOIN goals g ON g.id = kr.goal_id
+		WHERE g.organization_id = $1 AND g.id = $2 AND kr.id = $3
+		FOR UPDATE
+	`, checkIn.OrganizationID, checkIn.GoalID, checkIn.KeyResultID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("key result %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock key result: %w", err)
+	}
+	if state != types.GoalActive {
+		return nil, fmt.Errorf("goal is %s: %w", state, ErrInvalidState)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO goal_check_ins (id, organization_id, goal_id, key_result_id, value, confidence, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		RETURNING created_at
+	`, checkIn.ID, checkIn.OrganizationID, checkIn.GoalID, checkIn.KeyResultID, checkIn.Value, checkIn.Confidence,
+		checkIn.Note, checkIn.CreatedBy).Scan(&checkIn.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record check-in: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE goal_key_results SET current_value = $2, updated_at = now() WHERE id = $1
+	`, checkIn.KeyResultID, checkIn.Value); err != nil {
+		return nil, fmt.Errorf("failed to update key result: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE goals SET updated_by = $2, updated_at = now() WHERE id = $1
+	`, checkIn.GoalID, checkIn.CreatedBy); err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit check-in: %w", err)
+	}
+	return &checkIn, nil
+}
+
+// ListCheckIns lists a goal's check-ins, newest first
+func (r *PerformanceRepository) ListCheckIns(ctx context.Context, orgID, goalID uuid.UUID) ([]types.CheckIn, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, organization_id, goal_id, key_result_id, value, confidence, COALESCE(note, ''), created_by, created_at
+		FROM goal_check_ins
+		WHERE organization_id = $1 AND goal_id = $2
+		ORDER BY created_at DESC, id
+	`, orgID, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list check-ins: %w", err)
+	}
+	defer rows.Close()
+
+	checkIns := []types.CheckIn{}
+	for rows.Next() {
+		var c types.CheckIn
+		var confidence sql.NullInt64
+		var createdBy uuid.NullUUID
+		if err := rows.Scan(&c.ID, &c.OrganizationID, &c.GoalID, &c.KeyResultID, &c.Value, &confidence, &c.Note,
+			&createdBy, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan check-in: %w", err)
+		}
+		if confidence.Valid {
+			v := int(confidence.Int64)
+			c.Confidence = &v
+		}
+		c.CreatedBy = nullUUID(createdBy)
+		checkIns = append(checkIns, c)
+	}
+	return checkIns, rows.Err()
+}
+
+// OwnerUserIDs returns the users whose sales count toward a goal: the
+// employee's user, or the users of the department's current employees
+func (r *PerformanceRepository) OwnerUserIDs(ctx context.Context, orgID uuid.UUID, employeeID, departmentID *uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT user_id FROM employees
+		WHERE organization_id = $1 AND user_id IS NOT NULL AND deleted_at IS NULL
+			AND (id = $2::uuid OR (department_id = $3::uuid AND active AND date_terminated IS NULL))
+	`, orgID, employeeID, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goal owners: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan goal owner: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SalesActual sums the users' sales between the dates, both included: the
+// base amount of their commission accruals, which is their credited share
+// of each deal, or the expected revenue of the leads they won
+func (r *PerformanceRepository) SalesActual(ctx context.Context, orgID uuid.UUID, metric types.Metric, userIDs []uuid.UUID, from, to time.Time) (float64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	var query string
+	switch metric {
+	case types.MetricSalesCredit:
+		query = `
+			SELECT COALESCE(SUM(base_amount), 0) FROM commission_entries
+			WHERE organization_id = $1 AND entry_type = 'accrual' AND user_id = ANY($2::uuid[])
+				AND earned_on BETWEEN $3 AND $4`
+	case types.MetricWonRevenue:
+		query = `
+			SELECT COALESCE(SUM(expected_revenue), 0) FROM leads
+			WHERE organization_id = $1 AND won_status = 'won' AND deleted_at IS NULL
+				AND COALESCE(assigned_to, user_id) = ANY($2::uuid[])
+				AND date_closed::date BETWEEN $3 AND $4`
+	default:
+		return 0, fmt.Errorf("metric %s has no sales actual", metric)
+	}
+
+	var actual float64
+	if err := r.db.QueryRowContext(ctx, query, orgID, pq.Array(uuidStrings(userIDs)), from, to).Scan(&actual); err != nil {
+		return 0, fmt.Errorf("failed to sum sales: %w", err)
+	}
+	return actual, nil
+}
+
+const templateColumns = `id, organization_id, name, COALESCE(description, ''), questions, active, created_at, updated_at`
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*types.ReviewTemplate, error) {
+	var t types.ReviewTemplate
+	var questions []byte
+	if err := row.Scan(&t.ID, &t.OrganizationID, &t.Name, &t.Description, &questions, &t.Active,
+		&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(questions, &t.Questions); err != nil {
+		return nil, fmt.Errorf("failed to decode questions: %w", err)
+	}
+	if t.Questions == nil {
+		t.Questions = []types.Question{}
+	}
+	return &t, nil
+}
+
+func (r *PerformanceRepository) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.ReviewTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+templateColumns+` FROM review_templates WHERE organization_id = $1 ORDER BY name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []types.ReviewTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+func (r *PerformanceRepository) FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewTemplate, error) {
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, `
+		SELECT `+templateColumns+` FROM review_templates WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("review template %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find review template: %w", err)
+	}
+	return t, nil
+}
+
+func (r *PerformanceRepository) CreateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error) {
+	questions, err := json.Marshal(template.Questions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode questions: %w", err)
+	}
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, `
+		INSERT INTO review_templates (id, organization_id, name, description, questions, active, created_by, updated_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $7)
+		RETURNING `+templateColumns,
+		uuid.New(), template.OrganizationID, template.Name, template.Description, questions, template.Active, userID))
+	if err != nil {
+		if isUniqueViolation(err, "review_templates_name_unique") {
+			return nil, fmt.Errorf("review template %q %w", template.Name, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create review template: %w", err)
+	}
+	return t, nil
+}
+
+// UpdateTemplate replaces a review template. Cycles already open keep
+// validating answers against the updated questions.
+func (r *PerformanceRepository) UpdateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error) {
+	questions, err := json.Marshal(template.Questions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode questions: %w", err)
+	}
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, `
+		UPDATE review_templates SET name = $3, description = NULLIF($4, ''), questions = $5, active = $6,
+			updated_by = $7, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+templateColumns,
+		template.OrganizationID, template.ID, template.Name, template.Description, questions, template.Active, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("review template %w", ErrNotFound)
+		}
+		if isUniqueViolation(err, "review_templates_name_unique") {
+			return nil, fmt.Errorf("review template %q %w", template.Name, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update review template: %w", err)
+	}
+	return t, nil
+}
+
+const cycleColumns = `c.id, c.organization_id, c.name, c.template_id, c.department_id, c.period_start, c.period_end,
+	c.due_date, c.state,
+	(SELECT COUNT(*) FROM reviews rv WHERE rv.cycle_id = c.id),
+	(SELECT COUNT(*) FROM reviews rv WHERE rv.cycle_id = c.id AND rv.state = 'submitted'),
+	c.created_at, c.updated_at, c.created_by`
+
+func scanCycle(row interface{ Scan(...interface{}) error }) (*types.ReviewCycle, error) {
+	var c types.ReviewCycle
+	var departmentID, createdBy uuid.NullUUID
+	if err := row.Scan(&c.ID, &c.OrganizationID, &c.Name, &c.TemplateID, &departmentID, &c.PeriodStart, &c.PeriodEnd,
+		&c.DueDate, &c.State, &c.Reviews, &c.Submitted, &c.CreatedAt, &c.UpdatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	c.DepartmentID = nullUUID(departmentID)
+	c.CreatedBy = nullUUID(createdBy)
+	return &c, nil
+}
+
+func (r *PerformanceRepository) ListCycles(ctx context.Context, orgID uuid.UUID, state types.CycleState) ([]types.ReviewCycle, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+cycleColumns+` FROM review_cycles c
+		WHERE c.organization_id = $1 AND ($2 = '' OR c.state = $2)
+		ORDER BY c.period_start DESC, c.name
+	`, orgID, string(state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review cycles: %w", err)
+	}
+	defer rows.Close()
+
+	cycles := []types.ReviewCycle{}
+	for rows.Next() {
+		c, err := scanCycle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review cycle: %w", err)
+		}
+		cycles = append(cycles, *c)
+	}
+	return cycles, rows.Err()
+}
+
+func (r *PerformanceRepository) FindCycle(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewCycle, error) {
+	c, err := scanCycle(r.db.QueryRowContext(ctx, `
+		SELECT `+cycleColumns+` FROM review_cycles c WHERE c.organization_id = $1 AND c.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("review cycle %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find review cycle: %w", err)
+	}
+	return c, nil
+}
+
+func (r *PerformanceRepository) checkCycleReferences(ctx context.Context, cycle types.ReviewCycle) error {
+	if _, err := r.FindTemplate(ctx, cycle.OrganizationID, cycle.TemplateID); err != nil {
+		return err
+	}
+	return checkDepartment(ctx, r.db, cycle.OrganizationID, cycle.DepartmentID)
+}
+
+func (r *PerformanceRepository) CreateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error) {
+	if err := r.checkCycleReferences(ctx, cycle); err != nil {
+		return nil, err
+	}
+	id := uuid.New()
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO review_cycles (
+			id, organization_id, name, template_id, department_id, period_start, period_end, due_date, state,
+			created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'draft', $9, $9)
+	`, id, cycle.OrganizationID, cycle.Name, cycle.TemplateID, cycle.DepartmentID, cycle.PeriodStart, cycle.PeriodEnd,
+		cycle.DueDate, userID); err != nil {
+		return nil, fmt.Errorf("failed to create review cycle: %w", err)
+	}
+	return r.FindCycle(ctx, cycle.OrganizationID, id)
+}
+
+// UpdateCycle replaces a draft review cycle
+func (r *PerformanceRepository) UpdateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error) {
+	if err := r.checkCycleReferences(ctx, cycle); err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE review_cycles SET name = $3, template_id = $4, department_id = $5, period_start = $6, period_end = $7,
+			due_date = $8, updated_by = $9, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'draft'
+	`, cycle.OrganizationID, cycle.ID, cycle.Name, cycle.TemplateID, cycle.DepartmentID, cycle.PeriodStart,
+		cycle.PeriodEnd, cycle.DueDate, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update review cycle: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, r.cycleStateError(ctx, cycle.OrganizationID, cycle.ID)
+	}
+	return r.FindCycle(ctx, cycle.OrganizationID, cycle.ID)
+}
+
+// cycleStateError explains why a cycle did not change state: it does not
+// exist, or its state does not allow the change
+func (r *PerformanceRepository) cycleStateError(ctx context.Context, orgID, id uuid.UUID) error {
+	cycle, err := r.FindCycle(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("review cycle is %s: %w", cycle.State, ErrInvalidState)
+}
+
+func insertReviews(ctx context.Context, tx execer, reviews []types.Review) (int, error) {
+	created := 0
+	for _, rv := range reviews {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO reviews (id, organization_id, cycle_id, employee_id, reviewer_user_id, relationship, state)
+			VALUES ($1, $2, $3, $4, $5, $6, 'pending')
+			ON CONFLICT (cycle_id, employee_id, reviewer_user_id, relationship) DO NOTHING
+		`, uuid.New(), rv.OrganizationID, rv.CycleID, rv.EmployeeID, rv.ReviewerUserID, string(rv.Relationship))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create review: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		created += int(n)
+	}
+	return created, nil
+}
+
+// OpenCycle opens a draft cycle and creates its reviews
+func (r *PerformanceRepository) OpenCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reviews []types.Review) (*types.ReviewCycle, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE review_cycles SET state = 'open', updated_by = $3, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'draft'
+	`, orgID, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open review cycle: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, r.cycleStateError(ctx, orgID, id)
+	}
+
+	if _, err := insertReviews(ctx, tx, reviews); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit review cycle: %w", err)
+	}
+	return r.FindCycle(ctx, orgID, id)
+}
+
+// CloseCycle closes an open cycle. Pending reviews stay pending and can no
+// longer be submitted.
+func (r *PerformanceRepository) CloseCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID) (*types.ReviewCycle, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE review_cycles SET state = 'closed', updated_by = $3, updated_at = now()
+		WHERE organization_id = $1 AND id = $2 AND state = 'open'
+	`, orgID, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close review cycle: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, r.cycleStateError(ctx, orgID, id)
+	}
+	return r.FindCycle(ctx, orgID, id)
+}
+
+const employeeColumns = `e.id, e.name, e.user_id, e.department_id, COALESCE(m.user_id, dm.user_id)`
+
+const employeeJoins = `
+	FROM employees e
+	LEFT JOIN employees m ON m.id = e.parent_id AND m.deleted_at IS NULL
+	LEFT JOIN departments d ON d.id = e.department_id
+	LEFT JOIN employees dm ON dm.id = d.manager_id AND dm.id <> e.id AND dm.deleted_at IS NULL`
+
+func scanEmployee(row interface{ Scan(...interface{}) error }) (*types.Employee, error) {
+	var e types.Employee
+	var userID, departmentID, managerUserID uuid.NullUUID
+	if err := row.Scan(&e.ID, &e.Name, &userID, &departmentID, &managerUserID); err != nil {
+		return nil, err
+	}
+	e.UserID = nullUUID(userID)
+	e.DepartmentID = nullUUID(departmentID)
+	e.ManagerUserID = nullUUID(managerUserID)
+	return &e, nil
+}
+
+// ListEmployees lists the current employees of the organization, or of a
+// department, with the user of their manager
+func (r *PerformanceRepository) ListEmployees(ctx context.Context, orgID uuid.UUID, departmentID *uuid.UUID) ([]types.Employee, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+employeeColumns+employeeJoins+`
+		WHERE e.organization_id = $1 AND e.deleted_at IS NULL AND e.active AND e.date_terminated IS NULL
+			AND ($2::uuid IS NULL OR e.department_id = $2)
+		ORDER BY e.name, e.id
+	`, orgID, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+	defer rows.Close()
+
+	employees := []types.Employee{}
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan employee: %w", err)
+		}
+		employees = append(employees, *e)
+	}
+	return employees, rows.Err()
+}
+
+func (r *PerformanceRepository) FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error) {
+	e, err := scanEmployee(r.db.QueryRowContext(ctx, `
+		SELECT `+employeeColumns+employeeJoins+`
+		WHERE e.organization_id = $1 AND e.id = $2 AND e.deleted_at IS NULL
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("employee %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find employee: %w", err)
+	}
+	return e, nil
+}
+
+// AddReviews creates pending reviews, skipping those that already exist,
+// and returns how many were created
+func (r *PerformanceRepository) AddReviews(ctx context.Context, reviews []types.Review) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created, err := insertReviews(ctx, tx, reviews)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit reviews: %w", err)
+	}
+	return created, nil
+}
+
+const reviewColumns = `rv.id, rv.organization_id, rv.cycle_id, rv.employee_id, e.name, rv.reviewer_user_id,
+	rv.relationship, rv.state, rv.answers, rv.submitted_at, rv.created_at`
+
+func scanReview(row interface{ Scan(...interface{}) error }) (*types.Review, error) {
+	var rv types.Review
+	var answers []byte
+	var submittedAt sql.NullTime
+	if err := row.Scan(&rv.ID, &rv.OrganizationID, &rv.CycleID, &rv.EmployeeID, &rv.EmployeeName, &rv.ReviewerUserID,
+		&rv.Relationship, &rv.State, &answers, &submittedAt, &rv.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(answers, &rv.Answers); err != nil {
+		return nil, fmt.Errorf("failed to decode answers: %w", err)
+	}
+	if rv.Answers == nil {
+		rv.Answers = []types.Answer{}
+	}
+	if submittedAt.Valid {
+		rv.SubmittedAt = &submittedAt.Time
+	}
+	return &rv, nil
+}
+
+func (r *PerformanceRepository) ListReviews(ctx context.Context, filter types.ReviewFilter) ([]types.Review, error) {
+	where := []string{"rv.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.CycleID != nil {
+		args = append(args, *filter.CycleID)
+		where = append(where, fmt.Sprintf("rv.cycle_id = $%d", len(args)))
+	}
+	if filter.EmployeeID != nil {
+		args = append(args, *filter.EmployeeID)
+		where = append(where, fmt.Sprintf("rv.employee_id = $%d", len(args)))
+	}
+	if filter.ReviewerUserID != nil {
+		args = append(args, *filter.ReviewerUserID)
+		where = append(where, fmt.Sprintf("rv.reviewer_user_id = $%d", len(args)))
+	}
+	if filter.State != "" {
+		args = append(args, string(filter.State))
+		where = append(where, fmt.Sprintf("rv.state = $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reviewColumns+` FROM reviews rv
+		JOIN employees e ON e.id = rv.employee_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY e.name, rv.relationship, rv.created_at, rv.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []types.Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, *rv)
+	}
+	return reviews, rows.Err()
+}
+
+func (r *PerformanceRepository) FindReview(ctx context.Context, orgID, id uuid.UUID) (*types.Review, error) {
+	rv, err := scanReview(r.db.QueryRowContext(ctx, `
+		SELECT `+reviewColumns+` FROM reviews rv
+		JOIN employees e ON e.id = rv.employee_id
+		WHERE rv.organization_id = $1 AND rv.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("review %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find review: %w", err)
+	}
+	return rv, nil
+}
+
+// SubmitReview saves the answers of a pending review of an open cycle
+func (r *PerformanceRepository) SubmitReview(ctx context.Context, orgID, id uuid.UUID, answers []types.Answer, submittedAt time.Time) (*types.Review, error) {
+	encoded, err := json.Marshal(answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode answers: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE reviews rv SET answers = $3, state = 'submitted', submitted_at = $4
+		FROM review_cycles c
+		WHERE rv.organization_id = $1 AND rv.id = $2 AND rv.state = 'pending'
+			AND c.id = rv.cycle_id AND c.state = 'open'
+	`, orgID, id, encoded, submittedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit review: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.FindReview(ctx, orgID, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("review is submitted or its cycle is not open: %w", ErrInvalidState)
+	}
+	return r.FindReview(ctx, orgID, id)
+}
@@ -0,0 +1,995 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxKeyResults bounds the key results of a goal
+	MaxKeyResults = 20
+	// MaxQuestions bounds the questions of a review template
+	MaxQuestions = 50
+	// MaxPeerReviewers bounds the peers asked for feedback at once
+	MaxPeerReviewers = 20
+	// EventGoalCheckedIn is published when progress is checked in on a goal
+	EventGoalCheckedIn = "performance.goal_checked_in"
+	// EventCycleOpened is published when a review cycle opens and its reviews are created
+	EventCycleOpened = "performance.cycle_opened"
+	// EventReviewRequested is published for each review created, for notifying the reviewer
+	EventReviewRequested = "performance.review_requested"
+	// EventReviewSubmitted is published when a reviewer submits a review
+	EventReviewSubmitted = "performance.review_submitted"
+)
+
+var (
+	// ErrInvalid wraps validation failures of goals, check-ins, templates,
+	// cycles and reviews
+	ErrInvalid = errors.New("invalid request")
+	// ErrNotReviewer is returned when a user opens or submits a review
+	// assigned to someone else
+	ErrNotReviewer = errors.New("review is assigned to another reviewer")
+)
+
+// AuthService defines the permission check used by the performance service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// FiscalPeriods resolves fiscal period parameters in the organization's
+// fiscal calendar
+type FiscalPeriods interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error)
+}
+
+// PerformanceService tracks employee and department goals against their
+// key results, measuring sales key results from commission credits and won
+// leads, and runs review cycles collecting self, manager and peer feedback
+type PerformanceService struct {
+	repo        repository.PerformanceRepo
+	authService AuthService
+	eventBus    *events.Bus
+	periods     FiscalPeriods
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewPerformanceService(repo repository.PerformanceRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *PerformanceService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PerformanceService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetFiscalPeriods enables goals and quota attainment over fiscal periods
+func (s *PerformanceService) SetFiscalPeriods(periods FiscalPeriods) {
+	s.periods = periods
+}
+
+// resolvePeriod returns the fiscal period a parameter names
+func (s *PerformanceService) resolvePeriod(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error) {
+	if s.periods == nil {
+		return fiscal.Period{}, fmt.Errorf("%w: fiscal periods are not available", ErrInvalid)
+	}
+	period, err := s.periods.Resolve(ctx, orgID, spec)
+	if errors.Is(err, fiscal.ErrInvalidPeriod) {
+		return fiscal.Period{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return period, err
+}
+
+// resolveGoalPeriod sets the period of a goal given by fiscal period
+func (s *PerformanceService) resolveGoalPeriod(ctx context.Context, orgID uuid.UUID, request *types.GoalRequest) error {
+	if request.FiscalPeriod == "" {
+		return nil
+	}
+	if !request.PeriodStart.IsZero() || !request.PeriodEnd.IsZero() {
+		return fmt.Errorf("%w: fiscal_period cannot be combined with period_start and period_end", ErrInvalid)
+	}
+	period, err := s.resolvePeriod(ctx, orgID, request.FiscalPeriod)
+	if err != nil {
+		return err
+	}
+	request.PeriodStart, request.PeriodEnd = period.Start, period.End
+	return nil
+}
+
+func (s *PerformanceService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish performance event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// progress is how far the value moved from start to target, as a
+// percentage clamped between 0 and 100. Targets below the start measure
+// reductions.
+func progress(start, target, value float64) float64 {
+	if target == start {
+		return 0
+	}
+	p := (value - start) / (target - start)
+	p = math.Max(0, math.Min(1, p))
+	return math.Round(p*10000) / 100
+}
+
+// measure sets the progress of the goal's key results and of the goal, the
+// average of its key results. Sales key results are first brought up to
+// date with the owners' sales in the goal period.
+func (s *PerformanceService) measure(ctx context.Context, goal *types.Goal) error {
+	var owners []uuid.UUID
+	for i := range goal.KeyResults {
+		kr := &goal.KeyResults[i]
+		if kr.Metric.IsSales() {
+			if owners == nil {
+				ids, err := s.repo.OwnerUserIDs(ctx, goal.OrganizationID, goal.EmployeeID, goal.DepartmentID)
+				if err != nil {
+					return err
+				}
+				owners = ids
+			}
+			actual, err := s.repo.SalesActual(ctx, goal.OrganizationID, kr.Metric, owners, goal.PeriodStart, goal.PeriodEnd)
+			if err != nil {
+				return err
+			}
+			kr.CurrentValue = kr.StartValue + actual
+		}
+		kr.Progress = progress(kr.StartValue, kr.TargetValue, kr.CurrentValue)
+	}
+
+	goal.Progress = 0
+	if len(goal.KeyResults) > 0 {
+		total := 0.0
+		for _, kr := range goal.KeyResults {
+			total += kr.Progress
+		}
+		goal.Progress = math.Round(total/float64(len(goal.KeyResults))*100) / 100
+	}
+	return nil
+}
+
+func validateGoal(request *types.GoalRequest) error {
+	request.Title = strings.TrimSpace(request.Title)
+	request.Description = strings.TrimSpace(request.Description)
+	if request.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	if (request.EmployeeID == nil) == (request.DepartmentID == nil) {
+		return fmt.Errorf("%w: a goal belongs to either an employee or a department", ErrInvalid)
+	}
+	if request.PeriodStart.IsZero() || request.PeriodEnd.IsZero() {
+		return fmt.Errorf("%w: period_start and period_end are required", ErrInvalid)
+	}
+	if request.PeriodEnd.Before(request.PeriodStart) {
+		return fmt.Errorf("%w: period_end is before period_start", ErrInvalid)
+	}
+	if len(request.KeyResults) == 0 {
+		return fmt.Errorf("%w: a goal needs at least one key result", ErrInvalid)
+	}
+	if len(request.KeyResults) > MaxKeyResults {
+		return fmt.Errorf("%w: a goal has at most %d key results", ErrInvalid, MaxKeyResults)
+	}
+	seen := map[uuid.UUID]bool{}
+	for i := range request.KeyResults {
+		kr := &request.KeyResults[i]
+		kr.Title = strings.TrimSpace(kr.Title)
+		kr.Unit = strings.TrimSpace(kr.Unit)
+		if kr.Metric == "" {
+			kr.Metric = types.MetricManual
+		}
+		if kr.Title == "" {
+			return fmt.Errorf("%w: key result %d needs a title", ErrInvalid, i+1)
+		}
+		if !kr.Metric.IsValid() {
+			return fmt.Errorf("%w: key result %d has unknown metric %q", ErrInvalid, i+1, kr.Metric)
+		}
+		if kr.TargetValue == kr.StartValue {
+			return fmt.Errorf("%w: key result %d needs a target different from its start value", ErrInvalid, i+1)
+		}
+		if kr.Metric.IsSales() && (kr.StartValue < 0 || kr.TargetValue < kr.StartValue) {
+			return fmt.Errorf("%w: sales key result %d needs a target above its start value", ErrInvalid, i+1)
+		}
+		if kr.ID != nil {
+			if seen[*kr.ID] {
+				return fmt.Errorf("%w: key result %s is listed twice", ErrInvalid, *kr.ID)
+			}
+			seen[*kr.ID] = true
+		}
+	}
+	return nil
+}
+
+func applyGoalRequest(goal *types.Goal, request types.GoalRequest) {
+	goal.EmployeeID = request.EmployeeID
+	goal.DepartmentID = request.DepartmentID
+	goal.ParentID = request.ParentID
+	goal.Title = request.Title
+	goal.Description = request.Description
+	goal.PeriodStart = request.PeriodStart
+	goal.PeriodEnd = request.PeriodEnd
+	goal.KeyResults = make([]types.KeyResult, len(request.KeyResults))
+	for i, kr := range request.KeyResults {
+		goal.KeyResults[i] = types.KeyResult{
+			GoalID:      goal.ID,
+			Title:       kr.Title,
+			Metric:      kr.Metric,
+			Unit:        kr.Unit,
+			StartValue:  kr.StartValue,
+			TargetValue: kr.TargetValue,
+			Sequence:    (i + 1) * 10,
+		}
+		if kr.ID != nil {
+			goal.KeyResults[i].ID = *kr.ID
+		}
+	}
+}
+
+// ListGoals lists goals with their progress
+func (s *PerformanceService) ListGoals(ctx context.Context, filter types.GoalFilter) ([]types.Goal, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	if filter.Period != "" {
+		if filter.From != nil || filter.To != nil {
+			return nil, fmt.Errorf("%w: period cannot be combined with from and to", ErrInvalid)
+		}
+		period, err := s.resolvePeriod(ctx, filter.OrganizationID, filter.Period)
+		if err != nil {
+			return nil, err
+		}
+		filter.From, filter.To = &period.Start, &period.End
+	}
+	goals, err := s.repo.ListGoals(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals {
+		if err := s.measure(ctx, &goals[i]); err != nil {
+			return nil, err
+		}
+	}
+	return goals, nil
+}
+
+func (s *PerformanceService) GetGoal(ctx context.Context, orgID, id uuid.UUID) (*types.Goal, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	goal, err := s.repo.FindGoal(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.measure(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (s *PerformanceService) CreateGoal(ctx context.Context, orgID, userID uuid.UUID, request types.GoalRequest) (*types.Goal, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:goals"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.resolveGoalPeriod(ctx, orgID, &request); err != nil {
+		return nil, err
+	}
+	if err := validateGoal(&request); err != nil {
+		return nil, err
+	}
+	for _, kr := range request.KeyResults {
+		if kr.ID != nil {
+			return nil, fmt.Errorf("%w: a new goal has no existing key results", ErrInvalid)
+		}
+	}
+	goal := types.Goal{OrganizationID: orgID, State: types.GoalActive}
+	applyGoalRequest(&goal, request)
+	created, err := s.repo.CreateGoal(ctx, &userID, goal)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.measure(ctx, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateGoal replaces an active goal. Key results keep their progress when
+// they are listed with their ID.
+func (s *PerformanceService) UpdateGoal(ctx context.Context, orgID, userID, id uuid.UUID, request types.GoalRequest) (*types.Goal, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:goals"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.resolveGoalPeriod(ctx, orgID, &request); err != nil {
+		return nil, err
+	}
+	if err := validateGoal(&request); err != nil {
+		return nil, err
+	}
+	if request.ParentID != nil && *request.ParentID == id {
+		return nil, fmt.Errorf("%w: a goal cannot contribute to itself", ErrInvalid)
+	}
+	goal, err := s.repo.FindGoal(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyGoalRequest(goal, request)
+	updated, err := s.repo.UpdateGoal(ctx, &userID, *goal)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.measure(ctx, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// CompleteGoal closes an active goal as achieved, freezing its check-ins
+func (s *PerformanceService) CompleteGoal(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Goal, error) {
+	return s.setGoalState(ctx, orgID, userID, id, types.GoalCompleted)
+}
+
+// CancelGoal closes an active goal that is no longer pursued
+func (s *PerformanceService) CancelGoal(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Goal, error) {
+	return s.setGoalState(ctx, orgID, userID, id, types.GoalCancelled)
+}
+
+func (s *PerformanceService) setGoalState(ctx context.Context, orgID, userID, id uuid.UUID, state types.GoalState) (*types.Goal, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:goals"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	goal, err := s.repo.SetGoalState(ctx, orgID, id, &userID, state)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.measure(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// CheckIn records progress on a manual key result of an active goal. Sales
+// key results follow the sales actuals and take no check-ins.
+func (s *PerformanceService) CheckIn(ctx context.Context, orgID, userID, goalID uuid.UUID, request types.CheckInRequest) (*types.CheckIn, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:goals"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.Confidence != nil && (*request.Confidence < 0 || *request.Confidence > 10) {
+		return nil, fmt.Errorf("%w: confidence is between 0 and 10", ErrInvalid)
+	}
+	goal, err := s.repo.FindGoal(ctx, orgID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	var keyResult *types.KeyResult
+	for i := range goal.KeyResults {
+		if goal.KeyResults[i].ID == request.KeyResultID {
+			keyResult = &goal.KeyResults[i]
+		}
+	}
+	if keyResult == nil {
+		return nil, fmt.Errorf("key result %w", repository.ErrNotFound)
+	}
+	if keyResult.Metric.IsSales() {
+		return nil, fmt.Errorf("%w: %s key results are measured from sales and take no check-ins", ErrInvalid, keyResult.Metric)
+	}
+
+	checkIn, err := s.repo.CheckIn(ctx, types.CheckIn{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		GoalID:         goalID,
+		KeyResultID:    request.KeyResultID,
+		Value:          request.Value,
+		Confidence:     request.Confidence,
+		Note:           strings.TrimSpace(request.Note),
+		CreatedBy:      &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventGoalCheckedIn, checkIn)
+	return checkIn, nil
+}
+
+func (s *PerformanceService) ListCheckIns(ctx context.Context, orgID, goalID uuid.UUID) ([]types.CheckIn, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindGoal(ctx, orgID, goalID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListCheckIns(ctx, orgID, goalID)
+}
+
+// Attainment reports the sales key results of the goals against their
+// targets: the quota attainment of the employees and departments owning them
+func (s *PerformanceService) Attainment(ctx context.Context, filter types.GoalFilter) ([]types.Attainment, error) {
+	goals, err := s.ListGoals(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	attainments := []types.Attainment{}
+	for _, g := range goals {
+		for _, kr := range g.KeyResults {
+			if !kr.Metric.IsSales() {
+				continue
+			}
+			a := types.Attainment{
+				GoalID:       g.ID,
+				GoalTitle:    g.Title,
+				EmployeeID:   g.EmployeeID,
+				DepartmentID: g.DepartmentID,
+				KeyResultID:  kr.ID,
+				Title:        kr.Title,
+				Metric:       kr.Metric,
+				PeriodStart:  g.PeriodStart,
+				PeriodEnd:    g.PeriodEnd,
+				Target:       kr.TargetValue - kr.StartValue,
+				Actual:       kr.CurrentValue - kr.StartValue,
+			}
+			if a.Target > 0 {
+				a.Attainment = math.Round(a.Actual/a.Target*10000) / 100
+			}
+			attainments = append(attainments, a)
+		}
+	}
+	return attainments, nil
+}
+
+func validateTemplate(request *types.ReviewTemplateRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Description = strings.TrimSpace(request.Description)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if len(request.Questions) == 0 {
+		return fmt.Errorf("%w: a template needs at least one question", ErrInvalid)
+	}
+	if len(request.Questions) > MaxQuestions {
+		return fmt.Errorf("%w: a template has at most %d questions", ErrInvalid, MaxQuestions)
+	}
+	seen := map[string]bool{}
+	for i := range request.Questions {
+		q := &request.Questions[i]
+		q.ID = strings.TrimSpace(q.ID)
+		q.Text = strings.TrimSpace(q.Text)
+		if q.ID == "" || q.Text == "" {
+			return fmt.Errorf("%w: question %d needs an id and a text", ErrInvalid, i+1)
+		}
+		if seen[q.ID] {
+			return fmt.Errorf("%w: question id %q is used twice", ErrInvalid, q.ID)
+		}
+		seen[q.ID] = true
+		if q.Kind != types.QuestionRating && q.Kind != types.QuestionText {
+			return fmt.Errorf("%w: question %q has unknown kind %q", ErrInvalid, q.ID, q.Kind)
+		}
+	}
+	return nil
+}
+
+func applyTemplateRequest(template *types.ReviewTemplate, request types.ReviewTemplateRequest) {
+	template.Name = request.Name
+	template.Description = request.Description
+	template.Questions = request.Questions
+	if request.Active != nil {
+		template.Active = *request.Active
+	}
+}
+
+func (s *PerformanceService) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.ReviewTemplate, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListTemplates(ctx, orgID)
+}
+
+func (s *PerformanceService) GetTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewTemplate, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindTemplate(ctx, orgID, id)
+}
+
+func (s *PerformanceService) CreateTemplate(ctx context.Context, orgID, userID uuid.UUID, request types.ReviewTemplateRequest) (*types.ReviewTemplate, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateTemplate(&request); err != nil {
+		return nil, err
+	}
+	template := types.ReviewTemplate{OrganizationID: orgID, Active: true}
+	applyTemplateRequest(&template, request)
+	return s.repo.CreateTemplate(ctx, &userID, template)
+}
+
+// UpdateTemplate replaces a review template. Questions of cycles already
+// open should be kept, since submitted answers refer to them by ID.
+func (s *PerformanceService) UpdateTemplate(ctx context.Context, orgID, userID, id uuid.UUID, request types.ReviewTemplateRequest) (*types.ReviewTemplate, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateTemplate(&request); err != nil {
+		return nil, err
+	}
+	template, err := s.repo.FindTemplate(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyTemplateRequest(template, request)
+	return s.repo.UpdateTemplate(ctx, &userID, *template)
+}
+
+func validateCycle(request *types.ReviewCycleRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if request.TemplateID == uuid.Nil {
+		return fmt.Errorf("%w: template_id is required", ErrInvalid)
+	}
+	if request.PeriodStart.IsZero() || request.PeriodEnd.IsZero() || request.DueDate.IsZero() {
+		return fmt.Errorf("%w: period_start, period_end and due_date are required", ErrInvalid)
+	}
+	if request.PeriodEnd.Before(request.PeriodStart) {
+		return fmt.Errorf("%w: period_end is before period_start", ErrInvalid)
+	}
+	if request.DueDate.Before(request.PeriodStart) {
+		return fmt.Errorf("%w: due_date is before period_start", ErrInvalid)
+	}
+	return nil
+}
+
+func applyCycleRequest(cycle *types.ReviewCycle, request types.ReviewCycleRequest) {
+	cycle.Name = request.Name
+	cycle.TemplateID = request.TemplateID
+	cycle.DepartmentID = request.DepartmentID
+	cycle.PeriodStart = request.PeriodStart
+	cycle.PeriodEnd = request.PeriodEnd
+	cycle.DueDate = request.DueDate
+}
+
+func (s *PerformanceService) ListCycles(ctx context.Context, orgID uuid.UUID, state types.CycleState) ([]types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if state != "" && !state.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, state)
+	}
+	return s.repo.ListCycles(ctx, orgID, state)
+}
+
+func (s *PerformanceService) GetCycle(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindCycle(ctx, orgID, id)
+}
+
+func (s *PerformanceService) CreateCycle(ctx context.Context, orgID, userID uuid.UUID, request types.ReviewCycleRequest) (*types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCycle(&request); err != nil {
+		return nil, err
+	}
+	cycle := types.ReviewCycle{OrganizationID: orgID, State: types.CycleDraft}
+	applyCycleRequest(&cycle, request)
+	return s.repo.CreateCycle(ctx, &userID, cycle)
+}
+
+// UpdateCycle replaces a cycle that is not open yet
+func (s *PerformanceService) UpdateCycle(ctx context.Context, orgID, userID, id uuid.UUID, request types.ReviewCycleRequest) (*types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCycle(&request); err != nil {
+		return nil, err
+	}
+	cycle, err := s.repo.FindCycle(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyCycleRequest(cycle, request)
+	return s.repo.UpdateCycle(ctx, &userID, *cycle)
+}
+
+// OpenCycle opens a draft cycle with an active template, asking every
+// current employee in scope with a user for a self review and their
+// manager for a manager review
+func (s *PerformanceService) OpenCycle(ctx context.Context, orgID, userID, id uuid.UUID) (*types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	cycle, err := s.repo.FindCycle(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cycle.State != types.CycleDraft {
+		return nil, fmt.Errorf("review cycle is %s: %w", cycle.State, repository.ErrInvalidState)
+	}
+	template, err := s.repo.FindTemplate(ctx, orgID, cycle.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if !template.Active {
+		return nil, fmt.Errorf("%w: review template %q is archived", ErrInvalid, template.Name)
+	}
+
+	employees, err := s.repo.ListEmployees(ctx, orgID, cycle.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	reviews := []types.Review{}
+	for _, e := range employees {
+		if e.UserID != nil {
+			reviews = append(reviews, types.Review{
+				OrganizationID: orgID, CycleID: id, EmployeeID: e.ID,
+				ReviewerUserID: *e.UserID, Relationship: types.RelationshipSelf,
+			})
+		}
+		if e.ManagerUserID != nil && (e.UserID == nil || *e.ManagerUserID != *e.UserID) {
+			reviews = append(reviews, types.Review{
+				OrganizationID: orgID, CycleID: id, EmployeeID: e.ID,
+				ReviewerUserID: *e.ManagerUserID, Relationship: types.RelationshipManager,
+			})
+		}
+	}
+	if len(reviews) == 0 {
+		return nil, fmt.Errorf("%w: no employee in scope has a user to review", ErrInvalid)
+	}
+
+	opened, err := s.repo.OpenCycle(ctx, orgID, id, &userID, reviews)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventCycleOpened, opened)
+	s.publishReviewRequests(ctx, opened, reviews)
+	return opened, nil
+}
+
+func (s *PerformanceService) publishReviewRequests(ctx context.Context, cycle *types.ReviewCycle, reviews []types.Review) {
+	for _, rv := range reviews {
+		s.publishEvent(ctx, EventReviewRequested, map[string]interface{}{
+			"cycle_id":         cycle.ID,
+			"cycle_name":       cycle.Name,
+			"due_date":         cycle.DueDate,
+			"employee_id":      rv.EmployeeID,
+			"reviewer_user_id": rv.ReviewerUserID,
+			"relationship":     rv.Relationship,
+		})
+	}
+}
+
+// CloseCycle stops collecting feedback for a cycle
+func (s *PerformanceService) CloseCycle(ctx context.Context, orgID, userID, id uuid.UUID) (*types.ReviewCycle, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.CloseCycle(ctx, orgID, id, &userID)
+}
+
+// RequestPeerReviews asks colleagues for feedback on an employee in an open
+// cycle. Peers already asked are skipped; the count of new requests is
+// returned.
+func (s *PerformanceService) RequestPeerReviews(ctx context.Context, orgID, cycleID uuid.UUID, request types.PeerRequest) (int, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return 0, fmt.Errorf("permission denied: %w", err)
+	}
+	if len(request.ReviewerUserIDs) == 0 {
+		return 0, fmt.Errorf("%w: reviewer_user_ids is required", ErrInvalid)
+	}
+	if len(request.ReviewerUserIDs) > MaxPeerReviewers {
+		return 0, fmt.Errorf("%w: at most %d peers can be asked at once", ErrInvalid, MaxPeerReviewers)
+	}
+	cycle, err := s.repo.FindCycle(ctx, orgID, cycleID)
+	if err != nil {
+		return 0, err
+	}
+	if cycle.State != types.CycleOpen {
+		return 0, fmt.Errorf("review cycle is %s: %w", cycle.State, repository.ErrInvalidState)
+	}
+	employee, err := s.repo.FindEmployee(ctx, orgID, request.EmployeeID)
+	if err != nil {
+		return 0, err
+	}
+
+	reviews := []types.Review{}
+	seen := map[uuid.UUID]bool{}
+	for _, reviewer := range request.ReviewerUserIDs {
+		if employee.UserID != nil && reviewer == *employee.UserID {
+			return 0, fmt.Errorf("%w: employees cannot be their own peer", ErrInvalid)
+		}
+		if seen[reviewer] {
+			continue
+		}
+		seen[reviewer] = true
+		reviews = append(reviews, types.Review{
+			OrganizationID: orgID, CycleID: cycleID, EmployeeID: employee.ID,
+			ReviewerUserID: reviewer, Relationship: types.RelationshipPeer,
+		})
+	}
+
+	created, err := s.repo.AddReviews(ctx, reviews)
+	if err != nil {
+		return 0, err
+	}
+	s.publishReviewRequests(ctx, cycle, reviews)
+	return created, nil
+}
+
+func (s *PerformanceService) ListReviews(ctx context.Context, filter types.ReviewFilter) ([]types.Review, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	return s.repo.ListReviews(ctx, filter)
+}
+
+// MyReviews lists the reviews the user is asked to write
+func (s *PerformanceService) MyReviews(ctx context.Context, orgID, userID uuid.UUID, state types.ReviewState) ([]types.Review, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:review"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if state != "" && !state.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, state)
+	}
+	return s.repo.ListReviews(ctx, types.ReviewFilter{OrganizationID: orgID, ReviewerUserID: &userID, State: state})
+}
+
+// GetReview returns a review to its reviewer, or to review managers
+func (s *PerformanceService) GetReview(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Review, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:review"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	review, err := s.repo.FindReview(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.ReviewerUserID != userID {
+		if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+			return nil, ErrNotReviewer
+		}
+	}
+	return review, nil
+}
+
+// validateAnswers checks the answers against the template's questions:
+// every required question is answered, ratings are within range and no
+// answer refers to an unknown question
+func validateAnswers(questions []types.Question, answers []types.Answer) error {
+	byID := make(map[string]types.Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+	answered := map[string]bool{}
+	for i := range answers {
+		a := &answers[i]
+		a.Text = strings.TrimSpace(a.Text)
+		q, ok := byID[a.QuestionID]
+		if !ok {
+			return fmt.Errorf("%w: unknown question %q", ErrInvalid, a.QuestionID)
+		}
+		if answered[a.QuestionID] {
+			return fmt.Errorf("%w: question %q is answered twice", ErrInvalid, a.QuestionID)
+		}
+		switch q.Kind {
+		case types.QuestionRating:
+			if a.Rating == nil {
+				if a.Text == "" {
+					continue
+				}
+				return fmt.Errorf("%w: question %q needs a rating", ErrInvalid, q.ID)
+			}
+			if *a.Rating < 1 || *a.Rating > types.MaxRating {
+				return fmt.Errorf("%w: question %q is rated from 1 to %d", ErrInvalid, q.ID, types.MaxRating)
+			}
+		case types.QuestionText:
+			if a.Rating != nil {
+				return fmt.Errorf("%w: question %q takes no rating", ErrInvalid, q.ID)
+			}
+			if a.Text == "" {
+				continue
+			}
+		}
+		answered[a.QuestionID] = true
+	}
+	for _, q := range questions {
+		if q.Required && !answered[q.ID] {
+			return fmt.Errorf("%w: question %q is required", ErrInvalid, q.ID)
+		}
+	}
+	return nil
+}
+
+// SubmitReview submits the user's answers to a pending review of an open
+// cycle, validated against the cycle's template
+func (s *PerformanceService) SubmitReview(ctx context.Context, orgID, userID, id uuid.UUID, submission types.ReviewSubmission) (*types.Review, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:review"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	review, err := s.repo.FindReview(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.ReviewerUserID != userID {
+		return nil, ErrNotReviewer
+	}
+	if review.State != types.ReviewPending {
+		return nil, fmt.Errorf("review is %s: %w", review.State, repository.ErrInvalidState)
+	}
+	cycle, err := s.repo.FindCycle(ctx, orgID, review.CycleID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle.State != types.CycleOpen {
+		return nil, fmt.Errorf("review cycle is %s: %w", cycle.State, repository.ErrInvalidState)
+	}
+	template, err := s.repo.FindTemplate(ctx, orgID, cycle.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAnswers(template.Questions, submission.Answers); err != nil {
+		return nil, err
+	}
+	answers := []types.Answer{}
+	for _, a := range submission.Answers {
+		if a.Rating != nil || a.Text != "" {
+			answers = append(answers, a)
+		}
+	}
+
+	submitted, err := s.repo.SubmitReview(ctx, orgID, id, answers, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventReviewSubmitted, map[string]interface{}{
+		"review_id":    submitted.ID,
+		"cycle_id":     submitted.CycleID,
+		"employee_id":  submitted.EmployeeID,
+		"relationship": submitted.Relationship,
+	})
+	return submitted, nil
+}
+
+// Summary sums up the submitted feedback on an employee in a cycle: the
+// average rating of each rating question, overall and by relationship, and
+// the comments of text questions, peer comments left unattributed. The
+// employee's goals overlapping the cycle period come with it.
+func (s *PerformanceService) Summary(ctx context.Context, orgID, cycleID, employeeID uuid.UUID) (*types.ReviewSummary, error) {
+	if err := s.authService.CheckPermission(ctx, "performance:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	cycle, err := s.repo.FindCycle(ctx, orgID, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	employee, err := s.repo.FindEmployee(ctx, orgID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.repo.FindTemplate(ctx, orgID, cycle.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.repo.ListReviews(ctx, types.ReviewFilter{OrganizationID: orgID, CycleID: &cycleID, EmployeeID: &employeeID})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &types.ReviewSummary{
+		CycleID:      cycleID,
+		EmployeeID:   employeeID,
+		EmployeeName: employee.Name,
+		Reviews:      len(reviews),
+		Questions:    make([]types.QuestionSummary, len(template.Questions)),
+	}
+	index := make(map[string]int, len(template.Questions))
+	for i, q := range template.Questions {
+		index[q.ID] = i
+		summary.Questions[i] = types.QuestionSummary{QuestionID: q.ID, Text: q.Text, Kind: q.Kind}
+	}
+
+	type tally struct {
+		sum   float64
+		count int
+	}
+	overall := make([]tally, len(template.Questions))
+	byRelationship := make([]map[types.Relationship]*tally, len(template.Questions))
+
+	for _, rv := range reviews {
+		if rv.State != types.ReviewSubmitted {
+			continue
+		}
+		summary.Submitted++
+		for _, a := range rv.Answers {
+			i, ok := index[a.QuestionID]
+			if !ok {
+				// The question was removed from the template after the
+				// review was submitted
+				continue
+			}
+			qs := &summary.Questions[i]
+			qs.Responses++
+			if a.Rating != nil {
+				overall[i].sum += float64(*a.Rating)
+				overall[i].count++
+				if byRelationship[i] == nil {
+					byRelationship[i] = map[types.Relationship]*tally{}
+				}
+				t := byRelationship[i][rv.Relationship]
+				if t == nil {
+					t = &tally{}
+					byRelationship[i][rv.Relationship] = t
+				}
+				t.sum += float64(*a.Rating)
+				t.count++
+			}
+			if a.Text != "" {
+				qs.Comments = append(qs.Comments, types.Comment{Relationship: rv.Relationship, Text: a.Text})
+			}
+		}
+	}
+
+	for i := range summary.Questions {
+		qs := &summary.Questions[i]
+		if overall[i].count > 0 {
+			avg := math.Round(overall[i].sum/float64(overall[i].count)*100) / 100
+			qs.AverageRating = &avg
+			qs.ByRelationship = map[types.Relationship]float64{}
+			for rel, t := range byRelationship[i] {
+				qs.ByRelationship[rel] = math.Round(t.sum/float64(t.count)*100) / 100
+			}
+		}
+		// Group comments self, manager then peers
+		sort.SliceStable(qs.Comments, func(a, b int) bool {
+			return relationshipOrder[qs.Comments[a].Relationship] < relationshipOrder[qs.Comments[b].Relationship]
+		})
+	}
+
+	goals, err := s.repo.ListGoals(ctx, types.GoalFilter{
+		OrganizationID: orgID,
+		EmployeeID:     &employeeID,
+		From:           &cycle.PeriodStart,
+		To:             &cycle.PeriodEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals {
+		if err := s.measure(ctx, &goals[i]); err != nil {
+			return nil, err
+		}
+	}
+	summary.Goals = goals
+	return summary, nil
+}
+
+var relationshipOrder = map[types.Relationship]int{
+	types.RelationshipSelf:    0,
+	types.RelationshipManager: 1,
+	types.RelationshipPeer:    2,
+}
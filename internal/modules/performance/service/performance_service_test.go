@@ -0,0 +1,546 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/performance/types"
+	"github.com/KevTiv/alieze-erp/pkg/fiscal"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePerformanceRepo struct {
+	goals     []types.Goal
+	checkIns  []types.CheckIn
+	templates []types.ReviewTemplate
+	cycles    []types.ReviewCycle
+	employees []types.Employee
+	reviews   []types.Review
+	// owners are the sales users of each goal owner, employee or department
+	owners map[uuid.UUID][]uuid.UUID
+	// sales are the actuals of each metric per user
+	sales map[types.Metric]map[uuid.UUID]float64
+}
+
+func (f *fakePerformanceRepo) ListGoals(ctx context.Context, filter types.GoalFilter) ([]types.Goal, error) {
+	goals := []types.Goal{}
+	for _, g := range f.goals {
+		if filter.EmployeeID != nil && (g.EmployeeID == nil || *g.EmployeeID != *filter.EmployeeID) {
+			continue
+		}
+		if filter.From != nil && g.PeriodEnd.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && g.PeriodStart.After(*filter.To) {
+			continue
+		}
+		g.KeyResults = append([]types.KeyResult(nil), g.KeyResults...)
+		goals = append(goals, g)
+	}
+	return goals, nil
+}
+
+func (f *fakePerformanceRepo) FindGoal(ctx context.Context, orgID, id uuid.UUID) (*types.Goal, error) {
+	for _, g := range f.goals {
+		if g.ID == id {
+			g.KeyResults = append([]types.KeyResult(nil), g.KeyResults...)
+			return &g, nil
+		}
+	}
+	return nil, fmt.Errorf("goal %w", repository.ErrNotFound)
+}
+
+func (f *fakePerformanceRepo) CreateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error) {
+	goal.ID = uuid.New()
+	for i := range goal.KeyResults {
+		goal.KeyResults[i].ID = uuid.New()
+		goal.KeyResults[i].GoalID = goal.ID
+		goal.KeyResults[i].CurrentValue = goal.KeyResults[i].StartValue
+	}
+	f.goals = append(f.goals, goal)
+	return f.FindGoal(ctx, goal.OrganizationID, goal.ID)
+}
+
+func (f *fakePerformanceRepo) UpdateGoal(ctx context.Context, userID *uuid.UUID, goal types.Goal) (*types.Goal, error) {
+	return &goal, nil
+}
+
+func (f *fakePerformanceRepo) SetGoalState(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, state types.GoalState) (*types.Goal, error) {
+	for i := range f.goals {
+		if f.goals[i].ID == id {
+			f.goals[i].State = state
+		}
+	}
+	return f.FindGoal(ctx, orgID, id)
+}
+
+func (f *fakePerformanceRepo) CheckIn(ctx context.Context, checkIn types.CheckIn) (*types.CheckIn, error) {
+	for i := range f.goals {
+		for j := range f.goals[i].KeyResults {
+			if f.goals[i].KeyResults[j].ID == checkIn.KeyResultID {
+				f.goals[i].KeyResults[j].CurrentValue = checkIn.Value
+			}
+		}
+	}
+	f.checkIns = append(f.checkIns, checkIn)
+	return &checkIn, nil
+}
+
+func (f *fakePerformanceRepo) ListCheckIns(ctx context.Context, orgID, goalID uuid.UUID) ([]types.CheckIn, error) {
+	return f.checkIns, nil
+}
+
+func (f *fakePerformanceRepo) OwnerUserIDs(ctx context.Context, orgID uuid.UUID, employeeID, departmentID *uuid.UUID) ([]uuid.UUID, error) {
+	if employeeID != nil {
+		return f.owners[*employeeID], nil
+	}
+	return f.owners[*departmentID], nil
+}
+
+func (f *fakePerformanceRepo) SalesActual(ctx context.Context, orgID uuid.UUID, metric types.Metric, userIDs []uuid.UUID, from, to time.Time) (float64, error) {
+	total := 0.0
+	for _, id := range userIDs {
+		total += f.sales[metric][id]
+	}
+	return total, nil
+}
+
+func (f *fakePerformanceRepo) ListTemplates(ctx context.Context, orgID uuid.UUID) ([]types.ReviewTemplate, error) {
+	return f.templates, nil
+}
+
+func (f *fakePerformanceRepo) FindTemplate(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewTemplate, error) {
+	for _, t := range f.templates {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("review template %w", repository.ErrNotFound)
+}
+
+func (f *fakePerformanceRepo) CreateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error) {
+	template.ID = uuid.New()
+	f.templates = append(f.templates, template)
+	return &template, nil
+}
+
+func (f *fakePerformanceRepo) UpdateTemplate(ctx context.Context, userID *uuid.UUID, template types.ReviewTemplate) (*types.ReviewTemplate, error) {
+	return &template, nil
+}
+
+func (f *fakePerformanceRepo) ListCycles(ctx context.Context, orgID uuid.UUID, state types.CycleState) ([]types.ReviewCycle, error) {
+	return f.cycles, nil
+}
+
+func (f *fakePerformanceRepo) FindCycle(ctx context.Context, orgID, id uuid.UUID) (*types.ReviewCycle, error) {
+	for _, c := range f.cycles {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("review cycle %w", repository.ErrNotFound)
+}
+
+func (f *fakePerformanceRepo) CreateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error) {
+	cycle.ID = uuid.New()
+	f.cycles = append(f.cycles, cycle)
+	return &cycle, nil
+}
+
+func (f *fakePerformanceRepo) UpdateCycle(ctx context.Context, userID *uuid.UUID, cycle types.ReviewCycle) (*types.ReviewCycle, error) {
+	return &cycle, nil
+}
+
+func (f *fakePerformanceRepo) setCycleState(id uuid.UUID, state types.CycleState) {
+	for i := range f.cycles {
+		if f.cycles[i].ID == id {
+			f.cycles[i].State = state
+		}
+	}
+}
+
+func (f *fakePerformanceRepo) OpenCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reviews []types.Review) (*types.ReviewCycle, error) {
+	f.setCycleState(id, types.CycleOpen)
+	if _, err := f.AddReviews(ctx, reviews); err != nil {
+		return nil, err
+	}
+	return f.FindCycle(ctx, orgID, id)
+}
+
+func (f *fakePerformanceRepo) CloseCycle(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID) (*types.ReviewCycle, error) {
+	f.setCycleState(id, types.CycleClosed)
+	return f.FindCycle(ctx, orgID, id)
+}
+
+func (f *fakePerformanceRepo) ListEmployees(ctx context.Context, orgID uuid.UUID, departmentID *uuid.UUID) ([]types.Employee, error) {
+	return f.employees, nil
+}
+
+func (f *fakePerformanceRepo) FindEmployee(ctx context.Context, orgID, id uuid.UUID) (*types.Employee, error) {
+	for _, e := range f.employees {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("employee %w", repository.ErrNotFound)
+}
+
+func (f *fakePerformanceRepo) AddReviews(ctx context.Context, reviews []types.Review) (int, error) {
+	for _, rv := range reviews {
+		rv.ID = uuid.New()
+		rv.State = types.ReviewPending
+		f.reviews = append(f.reviews, rv)
+	}
+	return len(reviews), nil
+}
+
+func (f *fakePerformanceRepo) ListReviews(ctx context.Context, filter types.ReviewFilter) ([]types.Review, error) {
+	reviews := []types.Review{}
+	for _, rv := range f.reviews {
+		if filter.EmployeeID != nil && rv.EmployeeID != *filter.EmployeeID {
+			continue
+		}
+		reviews = append(reviews, rv)
+	}
+	return reviews, nil
+}
+
+func (f *fakePerformanceRepo) FindReview(ctx context.Context, orgID, id uuid.UUID) (*types.Review, error) {
+	for _, rv := range f.reviews {
+		if rv.ID == id {
+			return &rv, nil
+		}
+	}
+	return nil, fmt.Errorf("review %w", repository.ErrNotFound)
+}
+
+func (f *fakePerformanceRepo) SubmitReview(ctx context.Context, orgID, id uuid.UUID, answers []types.Answer, submittedAt time.Time) (*types.Review, error) {
+	for i := range f.reviews {
+		if f.reviews[i].ID == id {
+			f.reviews[i].Answers = answers
+			f.reviews[i].State = types.ReviewSubmitted
+			f.reviews[i].SubmittedAt = &submittedAt
+		}
+	}
+	return f.FindReview(ctx, orgID, id)
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+var (
+	orgID   = uuid.New()
+	q2Start = time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	q2End   = time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// fixture is a team of Grace, who manages Ada and Alan, with a Q2 review
+// cycle in draft
+type fixture struct {
+	svc                          *PerformanceService
+	repo                         *fakePerformanceRepo
+	grace, ada, alan             types.Employee
+	graceUser, adaUser, alanUser uuid.UUID
+	cycle                        types.ReviewCycle
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{graceUser: uuid.New(), adaUser: uuid.New(), alanUser: uuid.New()}
+	f.grace = types.Employee{ID: uuid.New(), Name: "Grace Hopper", UserID: &f.graceUser}
+	f.ada = types.Employee{ID: uuid.New(), Name: "Ada Lovelace", UserID: &f.adaUser, ManagerUserID: &f.graceUser}
+	f.alan = types.Employee{ID: uuid.New(), Name: "Alan Turing", UserID: &f.alanUser, ManagerUserID: &f.graceUser}
+
+	template := types.ReviewTemplate{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Name:           "Quarterly review",
+		Active:         true,
+		Questions: []types.Question{
+			{ID: "impact", Text: "How much impact did they have?", Kind: types.QuestionRating, Required: true},
+			{ID: "strengths", Text: "What should they keep doing?", Kind: types.QuestionText},
+		},
+	}
+	f.cycle = types.ReviewCycle{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Name:           "Q2 2025",
+		TemplateID:     template.ID,
+		PeriodStart:    q2Start,
+		PeriodEnd:      q2End,
+		DueDate:        time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC),
+		State:          types.CycleDraft,
+	}
+	f.repo = &fakePerformanceRepo{
+		templates: []types.ReviewTemplate{template},
+		cycles:    []types.ReviewCycle{f.cycle},
+		employees: []types.Employee{f.grace, f.ada, f.alan},
+		owners:    map[uuid.UUID][]uuid.UUID{},
+		sales:     map[types.Metric]map[uuid.UUID]float64{},
+	}
+	f.svc = NewPerformanceService(f.repo, allowAll{}, nil, nil)
+	f.svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	return f
+}
+
+func (f *fixture) reviewOf(t *testing.T, employee types.Employee, reviewer uuid.UUID) types.Review {
+	t.Helper()
+	for _, rv := range f.repo.reviews {
+		if rv.EmployeeID == employee.ID && rv.ReviewerUserID == reviewer {
+			return rv
+		}
+	}
+	t.Fatalf("no review of %s by %s", employee.Name, reviewer)
+	return types.Review{}
+}
+
+func rating(n int) *int { return &n }
+
+func TestCheckInMovesProgress(t *testing.T) {
+	f := newFixture(t)
+
+	goal, err := f.svc.CreateGoal(context.Background(), orgID, f.adaUser, types.GoalRequest{
+		EmployeeID:  &f.ada.ID,
+		Title:       "Ship the billing rewrite",
+		PeriodStart: q2Start,
+		PeriodEnd:   q2End,
+		KeyResults: []types.KeyResultRequest{
+			{Title: "Services migrated", StartValue: 0, TargetValue: 8},
+			{Title: "p95 latency in ms", StartValue: 400, TargetValue: 200},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.GoalActive, goal.State)
+	assert.Equal(t, types.MetricManual, goal.KeyResults[0].Metric)
+	assert.Zero(t, goal.Progress)
+
+	_, err = f.svc.CheckIn(context.Background(), orgID, f.adaUser, goal.ID, types.CheckInRequest{KeyResultID: goal.KeyResults[0].ID, Value: 6})
+	require.NoError(t, err)
+	_, err = f.svc.CheckIn(context.Background(), orgID, f.adaUser, goal.ID, types.CheckInRequest{KeyResultID: goal.KeyResults[1].ID, Value: 350})
+	require.NoError(t, err)
+
+	goal, err = f.svc.GetGoal(context.Background(), orgID, goal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, goal.KeyResults[0].Progress)
+	assert.Equal(t, 25.0, goal.KeyResults[1].Progress)
+	assert.Equal(t, 50.0, goal.Progress)
+
+	_, err = f.svc.CheckIn(context.Background(), orgID, f.adaUser, goal.ID, types.CheckInRequest{KeyResultID: goal.KeyResults[0].ID, Value: 7, Confidence: rating(11)})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = f.svc.CheckIn(context.Background(), orgID, f.adaUser, goal.ID, types.CheckInRequest{KeyResultID: uuid.New(), Value: 7})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestGoalValidation(t *testing.T) {
+	f := newFixture(t)
+	departmentID := uuid.New()
+	valid := func() types.GoalRequest {
+		return types.GoalRequest{
+			EmployeeID: &f.ada.ID, Title: "Grow", PeriodStart: q2Start, PeriodEnd: q2End,
+			KeyResults: []types.KeyResultRequest{{Title: "Deals", TargetValue: 10}},
+		}
+	}
+
+	for name, mutate := range map[string]func(*types.GoalRequest){
+		"no owner":        func(r *types.GoalRequest) { r.EmployeeID = nil },
+		"two owners":      func(r *types.GoalRequest) { r.DepartmentID = &departmentID },
+		"reversed period": func(r *types.GoalRequest) { r.PeriodEnd = q2Start.AddDate(0, 0, -1) },
+		"no key results":  func(r *types.GoalRequest) { r.KeyResults = nil },
+		"target at start": func(r *types.GoalRequest) { r.KeyResults[0].StartValue = 10 },
+		"unknown metric":  func(r *types.GoalRequest) { r.KeyResults[0].Metric = "vibes" },
+		"falling sales target": func(r *types.GoalRequest) {
+			r.KeyResults[0] = types.KeyResultRequest{Title: "Revenue", Metric: types.MetricWonRevenue, StartValue: 100, TargetValue: 50}
+		},
+	} {
+		request := valid()
+		mutate(&request)
+		_, err := f.svc.CreateGoal(context.Background(), orgID, f.adaUser, request)
+		assert.ErrorIs(t, err, ErrInvalid, name)
+	}
+	assert.Empty(t, f.repo.goals)
+}
+
+// aprilYear resolves periods of fiscal years starting in April
+type aprilYear struct{ today time.Time }
+
+func (p aprilYear) Resolve(ctx context.Context, orgID uuid.UUID, spec string) (fiscal.Period, error) {
+	cal := &fiscal.Calendar{StartMonth: time.April}
+	if err := cal.Validate(); err != nil {
+		return fiscal.Period{}, err
+	}
+	return cal.Parse(spec, p.today)
+}
+
+func TestGoalsOverFiscalPeriods(t *testing.T) {
+	f := newFixture(t)
+	f.svc.SetFiscalPeriods(aprilYear{today: f.svc.now()})
+	f.repo.goals = nil
+
+	goal, err := f.svc.CreateGoal(context.Background(), orgID, f.adaUser, types.GoalRequest{
+		EmployeeID: &f.ada.ID, Title: "Grow", FiscalPeriod: "FY2026-Q1",
+		KeyResults: []types.KeyResultRequest{{Title: "Deals", TargetValue: 10}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, q2Start, goal.PeriodStart)
+	assert.Equal(t, q2End, goal.PeriodEnd)
+
+	goals, err := f.svc.ListGoals(context.Background(), types.GoalFilter{OrganizationID: orgID, Period: "next-quarter"})
+	require.NoError(t, err)
+	assert.Len(t, goals, 1)
+	goals, err = f.svc.ListGoals(context.Background(), types.GoalFilter{OrganizationID: orgID, Period: "current-quarter"})
+	require.NoError(t, err)
+	assert.Empty(t, goals)
+
+	_, err = f.svc.ListGoals(context.Background(), types.GoalFilter{OrganizationID: orgID, Period: "FY26"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = f.svc.CreateGoal(context.Background(), orgID, f.adaUser, types.GoalRequest{
+		EmployeeID: &f.ada.ID, Title: "Grow", FiscalPeriod: "FY2026-Q1", PeriodStart: q2Start,
+		KeyResults: []types.KeyResultRequest{{Title: "Deals", TargetValue: 10}},
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestSalesKeyResultsFollowAttainment(t *testing.T) {
+	f := newFixture(t)
+	departmentID := uuid.New()
+	f.repo.owners[departmentID] = []uuid.UUID{f.adaUser, f.alanUser}
+	f.repo.sales[types.MetricSalesCredit] = map[uuid.UUID]float64{f.adaUser: 30000, f.alanUser: 15000}
+
+	goal, err := f.svc.CreateGoal(context.Background(), orgID, f.graceUser, types.GoalRequest{
+		DepartmentID: &departmentID,
+		Title:        "Q2 quota",
+		PeriodStart:  q2Start,
+		PeriodEnd:    q2End,
+		KeyResults: []types.KeyResultRequest{
+			{Title: "Credited bookings", Metric: types.MetricSalesCredit, Unit: "USD", TargetValue: 60000},
+			{Title: "Partner launches", TargetValue: 2},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 45000.0, goal.KeyResults[0].CurrentValue)
+	assert.Equal(t, 75.0, goal.KeyResults[0].Progress)
+	assert.Equal(t, 37.5, goal.Progress)
+
+	_, err = f.svc.CheckIn(context.Background(), orgID, f.graceUser, goal.ID, types.CheckInRequest{KeyResultID: goal.KeyResults[0].ID, Value: 60000})
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Empty(t, f.repo.checkIns)
+
+	f.repo.sales[types.MetricSalesCredit][f.alanUser] = 45000
+	attainment, err := f.svc.Attainment(context.Background(), types.GoalFilter{OrganizationID: orgID})
+	require.NoError(t, err)
+	require.Len(t, attainment, 1)
+	assert.Equal(t, goal.KeyResults[0].ID, attainment[0].KeyResultID)
+	assert.Equal(t, 60000.0, attainment[0].Target)
+	assert.Equal(t, 75000.0, attainment[0].Actual)
+	assert.Equal(t, 125.0, attainment[0].Attainment)
+}
+
+func TestOpenCycleRequestsSelfAndManagerReviews(t *testing.T) {
+	f := newFixture(t)
+
+	cycle, err := f.svc.OpenCycle(context.Background(), orgID, f.graceUser, f.cycle.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.CycleOpen, cycle.State)
+
+	// Grace reports to no one, so she only reviews herself
+	require.Len(t, f.repo.reviews, 5)
+	assert.Equal(t, types.RelationshipSelf, f.reviewOf(t, f.grace, f.graceUser).Relationship)
+	assert.Equal(t, types.RelationshipSelf, f.reviewOf(t, f.ada, f.adaUser).Relationship)
+	assert.Equal(t, types.RelationshipManager, f.reviewOf(t, f.ada, f.graceUser).Relationship)
+	assert.Equal(t, types.RelationshipManager, f.reviewOf(t, f.alan, f.graceUser).Relationship)
+
+	_, err = f.svc.OpenCycle(context.Background(), orgID, f.graceUser, f.cycle.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+
+	_, err = f.svc.RequestPeerReviews(context.Background(), orgID, f.cycle.ID, types.PeerRequest{EmployeeID: f.ada.ID, ReviewerUserIDs: []uuid.UUID{f.adaUser}})
+	assert.ErrorIs(t, err, ErrInvalid)
+	requested, err := f.svc.RequestPeerReviews(context.Background(), orgID, f.cycle.ID, types.PeerRequest{EmployeeID: f.ada.ID, ReviewerUserIDs: []uuid.UUID{f.alanUser, f.alanUser}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requested)
+	assert.Equal(t, types.RelationshipPeer, f.reviewOf(t, f.ada, f.alanUser).Relationship)
+}
+
+func TestSubmitReviewValidatesAnswers(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.svc.OpenCycle(context.Background(), orgID, f.graceUser, f.cycle.ID)
+	require.NoError(t, err)
+	review := f.reviewOf(t, f.ada, f.graceUser)
+
+	for name, answers := range map[string][]types.Answer{
+		"required question unanswered": {{QuestionID: "strengths", Text: "Clear writing"}},
+		"rating above the scale":       {{QuestionID: "impact", Rating: rating(types.MaxRating + 1)}},
+		"rating below the scale":       {{QuestionID: "impact", Rating: rating(0)}},
+		"unknown question":             {{QuestionID: "impact", Rating: rating(4)}, {QuestionID: "salary", Text: "More"}},
+		"rating on a text question":    {{QuestionID: "impact", Rating: rating(4)}, {QuestionID: "strengths", Rating: rating(5)}},
+	} {
+		_, err := f.svc.SubmitReview(context.Background(), orgID, f.graceUser, review.ID, types.ReviewSubmission{Answers: answers})
+		assert.ErrorIs(t, err, ErrInvalid, name)
+	}
+
+	submission := types.ReviewSubmission{Answers: []types.Answer{{QuestionID: "impact", Rating: rating(4)}}}
+	_, err = f.svc.SubmitReview(context.Background(), orgID, f.adaUser, review.ID, submission)
+	assert.ErrorIs(t, err, ErrNotReviewer)
+
+	submitted, err := f.svc.SubmitReview(context.Background(), orgID, f.graceUser, review.ID, submission)
+	require.NoError(t, err)
+	assert.Equal(t, types.ReviewSubmitted, submitted.State)
+	assert.Equal(t, time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC), *submitted.SubmittedAt)
+
+	_, err = f.svc.SubmitReview(context.Background(), orgID, f.graceUser, review.ID, submission)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+
+	f.repo.setCycleState(f.cycle.ID, types.CycleClosed)
+	_, err = f.svc.SubmitReview(context.Background(), orgID, f.adaUser, f.reviewOf(t, f.ada, f.adaUser).ID, submission)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+}
+
+func TestSummaryAveragesRatingsAndKeepsPeersAnonymous(t *testing.T) {
+	f := newFixture(t)
+	_, err := f.svc.OpenCycle(context.Background(), orgID, f.graceUser, f.cycle.ID)
+	require.NoError(t, err)
+	_, err = f.svc.RequestPeerReviews(context.Background(), orgID, f.cycle.ID, types.PeerRequest{EmployeeID: f.ada.ID, ReviewerUserIDs: []uuid.UUID{f.alanUser}})
+	require.NoError(t, err)
+
+	submit := func(reviewer uuid.UUID, impact int, strengths string) {
+		_, err := f.svc.SubmitReview(context.Background(), orgID, reviewer, f.reviewOf(t, f.ada, reviewer).ID, types.ReviewSubmission{
+			Answers: []types.Answer{{QuestionID: "impact", Rating: &impact}, {QuestionID: "strengths", Text: strengths}},
+		})
+		require.NoError(t, err)
+	}
+	submit(f.alanUser, 5, "Unblocks everyone")
+	submit(f.graceUser, 4, "Owns the billing rewrite")
+
+	_, err = f.svc.CreateGoal(context.Background(), orgID, f.adaUser, types.GoalRequest{
+		EmployeeID: &f.ada.ID, Title: "Ship the billing rewrite", PeriodStart: q2Start, PeriodEnd: q2End,
+		KeyResults: []types.KeyResultRequest{{Title: "Services migrated", TargetValue: 8}},
+	})
+	require.NoError(t, err)
+
+	summary, err := f.svc.Summary(context.Background(), orgID, f.cycle.ID, f.ada.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", summary.EmployeeName)
+	assert.Equal(t, 3, summary.Reviews)
+	assert.Equal(t, 2, summary.Submitted)
+
+	impact := summary.Questions[0]
+	require.NotNil(t, impact.AverageRating)
+	assert.Equal(t, 4.5, *impact.AverageRating)
+	assert.Equal(t, map[types.Relationship]float64{types.RelationshipManager: 4, types.RelationshipPeer: 5}, impact.ByRelationship)
+
+	strengths := summary.Questions[1]
+	assert.Nil(t, strengths.AverageRating)
+	assert.Equal(t, []types.Comment{
+		{Relationship: types.RelationshipManager, Text: "Owns the billing rewrite"},
+		{Relationship: types.RelationshipPeer, Text: "Unblocks everyone"},
+	}, strengths.Comments)
+
+	require.Len(t, summary.Goals, 1)
+	assert.Equal(t, "Ship the billing rewrite", summary.Goals[0].Title)
+}
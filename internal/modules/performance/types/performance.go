@@ -0,0 +1,351 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GoalState is the lifecycle state of a goal
+type GoalState string
+
+const (
+	GoalActive    GoalState = "active"
+	GoalCompleted GoalState = "completed"
+	GoalCancelled GoalState = "cancelled"
+)
+
+// IsValid reports whether the state is known
+func (s GoalState) IsValid() bool {
+	return s == GoalActive || s == GoalCompleted || s == GoalCancelled
+}
+
+// Metric is how a key result is measured
+type Metric string
+
+const (
+	// MetricManual key results are updated by check-ins
+	MetricManual Metric = "manual"
+	// MetricSalesCredit key results measure the commission credit the
+	// goal's owners earned in the goal period, their quota attainment
+	MetricSalesCredit Metric = "sales_credit"
+	// MetricWonRevenue key results measure the expected revenue of leads
+	// the goal's owners won in the goal period
+	MetricWonRevenue Metric = "won_revenue"
+)
+
+// IsValid reports whether the metric is known
+func (m Metric) IsValid() bool {
+	return m == MetricManual || m == MetricSalesCredit || m == MetricWonRevenue
+}
+
+// IsSales reports whether the key result tracks sales actuals rather than
+// check-ins
+func (m Metric) IsSales() bool {
+	return m == MetricSalesCredit || m == MetricWonRevenue
+}
+
+// KeyResult is a measurable result of a goal
+type KeyResult struct {
+	ID           uuid.UUID `json:"id"`
+	GoalID       uuid.UUID `json:"goal_id"`
+	Title        string    `json:"title"`
+	Metric       Metric    `json:"metric"`
+	Unit         string    `json:"unit,omitempty"`
+	StartValue   float64   `json:"start_value"`
+	TargetValue  float64   `json:"target_value"`
+	CurrentValue float64   `json:"current_value"`
+	// Progress is how far the current value moved from the start value to
+	// the target, as a percentage between 0 and 100
+	Progress  float64   `json:"progress"`
+	Sequence  int       `json:"sequence"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// KeyResultRequest creates a key result, or keeps the one with ID
+type KeyResultRequest struct {
+	ID          *uuid.UUID `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Metric      Metric     `json:"metric"`
+	Unit        string     `json:"unit"`
+	StartValue  float64    `json:"start_value"`
+	TargetValue float64    `json:"target_value"`
+}
+
+// Goal is an objective of an employee or a department for a period
+type Goal struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	EmployeeID     *uuid.UUID `json:"employee_id,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	// ParentID is the goal this one contributes to
+	ParentID    *uuid.UUID  `json:"parent_id,omitempty"`
+	Title       string      `json:"title"`
+	Description string      `json:"description,omitempty"`
+	PeriodStart time.Time   `json:"period_start"`
+	PeriodEnd   time.Time   `json:"period_end"`
+	State       GoalState   `json:"state"`
+	Progress    float64     `json:"progress"`
+	KeyResults  []KeyResult `json:"key_results"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CreatedBy   *uuid.UUID  `json:"created_by,omitempty"`
+}
+
+// GoalRequest creates or replaces a goal. Key results missing from an
+// update are deleted with their check-ins. A fiscal period, such as
+// FY2025-Q2, sets the period in the organization's fiscal calendar instead
+// of period_start and period_end.
+type GoalRequest struct {
+	EmployeeID   *uuid.UUID         `json:"employee_id,omitempty"`
+	DepartmentID *uuid.UUID         `json:"department_id,omitempty"`
+	ParentID     *uuid.UUID         `json:"parent_id,omitempty"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	PeriodStart  time.Time          `json:"period_start"`
+	PeriodEnd    time.Time          `json:"period_end"`
+	FiscalPeriod string             `json:"fiscal_period,omitempty"`
+	KeyResults   []KeyResultRequest `json:"key_results"`
+}
+
+// GoalFilter selects goals
+type GoalFilter struct {
+	OrganizationID uuid.UUID
+	EmployeeID     *uuid.UUID
+	DepartmentID   *uuid.UUID
+	State          GoalState
+	// From and To select goals whose period overlaps them
+	From *time.Time
+	To   *time.Time
+	// Period is a fiscal period parameter, such as FY2025-Q2, setting From
+	// and To
+	Period string
+}
+
+// CheckIn records progress on a key result
+type CheckIn struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	GoalID         uuid.UUID  `json:"goal_id"`
+	KeyResultID    uuid.UUID  `json:"key_result_id"`
+	Value          float64    `json:"value"`
+	Confidence     *int       `json:"confidence,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CheckInRequest records progress on a key result. Confidence is how
+// likely the owner thinks the target is met, from 0 to 10.
+type CheckInRequest struct {
+	KeyResultID uuid.UUID `json:"key_result_id"`
+	Value       float64   `json:"value"`
+	Confidence  *int      `json:"confidence,omitempty"`
+	Note        string    `json:"note"`
+}
+
+// Attainment is a sales key result's actual against its target
+type Attainment struct {
+	GoalID       uuid.UUID  `json:"goal_id"`
+	GoalTitle    string     `json:"goal_title"`
+	EmployeeID   *uuid.UUID `json:"employee_id,omitempty"`
+	DepartmentID *uuid.UUID `json:"department_id,omitempty"`
+	KeyResultID  uuid.UUID  `json:"key_result_id"`
+	Title        string     `json:"title"`
+	Metric       Metric     `json:"metric"`
+	PeriodStart  time.Time  `json:"period_start"`
+	PeriodEnd    time.Time  `json:"period_end"`
+	Target       float64    `json:"target"`
+	Actual       float64    `json:"actual"`
+	// Attainment is the actual as a percentage of the target; it can
+	// exceed 100
+	Attainment float64 `json:"attainment"`
+}
+
+// QuestionKind is how a review question is answered
+type QuestionKind string
+
+const (
+	QuestionRating QuestionKind = "rating"
+	QuestionText   QuestionKind = "text"
+)
+
+// MaxRating is the highest answer of rating questions, rated from 1
+const MaxRating = 5
+
+// Question is a question of a review template
+type Question struct {
+	ID       string       `json:"id"`
+	Text     string       `json:"text"`
+	Kind     QuestionKind `json:"kind"`
+	Required bool         `json:"required"`
+}
+
+// ReviewTemplate is the questionnaire of review cycles
+type ReviewTemplate struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description,omitempty"`
+	Questions      []Question `json:"questions"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ReviewTemplateRequest creates or replaces a review template
+type ReviewTemplateRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Questions   []Question `json:"questions"`
+	Active      *bool      `json:"active,omitempty"`
+}
+
+// CycleState is the lifecycle state of a review cycle
+type CycleState string
+
+const (
+	CycleDraft  CycleState = "draft"
+	CycleOpen   CycleState = "open"
+	CycleClosed CycleState = "closed"
+)
+
+// IsValid reports whether the state is known
+func (s CycleState) IsValid() bool {
+	return s == CycleDraft || s == CycleOpen || s == CycleClosed
+}
+
+// ReviewCycle is a round of reviews of the employees of an organization,
+// or of a department, over a period
+type ReviewCycle struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	TemplateID     uuid.UUID  `json:"template_id"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	PeriodStart    time.Time  `json:"period_start"`
+	PeriodEnd      time.Time  `json:"period_end"`
+	DueDate        time.Time  `json:"due_date"`
+	State          CycleState `json:"state"`
+	Reviews        int        `json:"reviews"`
+	Submitted      int        `json:"submitted"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+}
+
+// ReviewCycleRequest creates or replaces a draft review cycle
+type ReviewCycleRequest struct {
+	Name         string     `json:"name"`
+	TemplateID   uuid.UUID  `json:"template_id"`
+	DepartmentID *uuid.UUID `json:"department_id,omitempty"`
+	PeriodStart  time.Time  `json:"period_start"`
+	PeriodEnd    time.Time  `json:"period_end"`
+	DueDate      time.Time  `json:"due_date"`
+}
+
+// Relationship is who a reviewer is to the employee reviewed
+type Relationship string
+
+const (
+	RelationshipSelf    Relationship = "self"
+	RelationshipManager Relationship = "manager"
+	RelationshipPeer    Relationship = "peer"
+)
+
+// ReviewState is the state of a review
+type ReviewState string
+
+const (
+	ReviewPending   ReviewState = "pending"
+	ReviewSubmitted ReviewState = "submitted"
+)
+
+// IsValid reports whether the state is known
+func (s ReviewState) IsValid() bool {
+	return s == ReviewPending || s == ReviewSubmitted
+}
+
+// Answer answers a review question
+type Answer struct {
+	QuestionID string `json:"question_id"`
+	Rating     *int   `json:"rating,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// Review is the feedback of one reviewer on one employee in a cycle
+type Review struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	CycleID        uuid.UUID    `json:"cycle_id"`
+	EmployeeID     uuid.UUID    `json:"employee_id"`
+	EmployeeName   string       `json:"employee_name"`
+	ReviewerUserID uuid.UUID    `json:"reviewer_user_id"`
+	Relationship   Relationship `json:"relationship"`
+	State          ReviewState  `json:"state"`
+	Answers        []Answer     `json:"answers"`
+	SubmittedAt    *time.Time   `json:"submitted_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// ReviewFilter selects reviews
+type ReviewFilter struct {
+	OrganizationID uuid.UUID
+	CycleID        *uuid.UUID
+	EmployeeID     *uuid.UUID
+	ReviewerUserID *uuid.UUID
+	State          ReviewState
+}
+
+// PeerRequest asks colleagues for feedback on an employee
+type PeerRequest struct {
+	EmployeeID      uuid.UUID   `json:"employee_id"`
+	ReviewerUserIDs []uuid.UUID `json:"reviewer_user_ids"`
+}
+
+// ReviewSubmission submits a review's answers
+type ReviewSubmission struct {
+	Answers []Answer `json:"answers"`
+}
+
+// Employee is an employee as reviews need them
+type Employee struct {
+	ID           uuid.UUID
+	Name         string
+	UserID       *uuid.UUID
+	DepartmentID *uuid.UUID
+	// ManagerUserID is the user of the employee's manager, or of their
+	// department's manager when they have none
+	ManagerUserID *uuid.UUID
+}
+
+// Comment is a text answer in a review summary. Peer comments are not
+// attributed to their reviewer.
+type Comment struct {
+	Relationship Relationship `json:"relationship"`
+	Text         string       `json:"text"`
+}
+
+// QuestionSummary sums up the submitted answers to a question
+type QuestionSummary struct {
+	QuestionID string       `json:"question_id"`
+	Text       string       `json:"text"`
+	Kind       QuestionKind `json:"kind"`
+	Responses  int          `json:"responses"`
+	// AverageRating and ByRelationship are set for rating questions
+	AverageRating  *float64                 `json:"average_rating,omitempty"`
+	ByRelationship map[Relationship]float64 `json:"by_relationship,omitempty"`
+	Comments       []Comment                `json:"comments,omitempty"`
+}
+
+// ReviewSummary is an employee's submitted feedback in a cycle, with their
+// goals for the cycle period
+type ReviewSummary struct {
+	CycleID      uuid.UUID         `json:"cycle_id"`
+	EmployeeID   uuid.UUID         `json:"employee_id"`
+	EmployeeName string            `json:"employee_name"`
+	Reviews      int               `json:"reviews"`
+	Submitted    int               `json:"submitted"`
+	Questions    []QuestionSummary `json:"questions"`
+	Goals        []Goal            `json:"goals"`
+}
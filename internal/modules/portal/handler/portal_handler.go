@@ -0,0 +1,686 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PortalHandler handles the customer portal and the staff endpoints that
+// manage portal accounts, support tickets and return requests
+type PortalHandler struct {
+	service *service.PortalService
+	logger  *slog.Logger
+}
+
+func NewPortalHandler(service *service.PortalService, logger *slog.Logger) *PortalHandler {
+	return &PortalHandler{service: service, logger: logger}
+}
+
+func (h *PortalHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/portal-accounts", h.ListAccounts)
+	router.POST("/api/v1/portal-accounts", h.InviteAccount)
+	router.POST("/api/v1/portal-accounts/:id/invite", h.ResendInvite)
+	router.POST("/api/v1/portal-accounts/:id/disable", h.DisableAccount)
+	router.GET("/api/v1/support-tickets", h.ListSupportTickets)
+	router.PUT("/api/v1/support-tickets/:id/status", h.SetTicketStatus)
+	router.GET("/api/v1/return-requests", h.ListReturnRequests)
+	router.PUT("/api/v1/return-requests/:id/status", h.SetReturnStatus)
+
+	// Customer endpoints, authorized by a portal session token
+	router.POST("/public/v1/portal/auth/magic-link", h.RequestMagicLink)
+	router.POST("/public/v1/portal/auth/magic-link/redeem", h.RedeemMagicLink)
+	router.POST("/public/v1/portal/auth/login", h.Login)
+	router.POST("/public/v1/portal/auth/logout", h.Logout)
+	router.GET("/public/v1/portal/account", h.Account)
+	router.PUT("/public/v1/portal/account/password", h.SetPassword)
+	router.GET("/public/v1/portal/quotes", h.ListQuotes)
+	router.GET("/public/v1/portal/quotes/:id", h.GetOrder)
+	router.POST("/public/v1/portal/quotes/:id/approve", h.ApproveQuote)
+	router.GET("/public/v1/portal/orders", h.ListOrders)
+	router.GET("/public/v1/portal/orders/:id", h.GetOrder)
+	router.GET("/public/v1/portal/invoices", h.ListInvoices)
+	router.GET("/public/v1/portal/invoices/:id", h.GetInvoice)
+	router.GET("/public/v1/portal/invoices/:id/pdf", h.InvoicePDF)
+	router.GET("/public/v1/portal/shipments", h.ListShipments)
+	router.GET("/public/v1/portal/shipments/:id/tracking", h.TrackShipment)
+	router.GET("/public/v1/portal/tickets", h.ListTickets)
+	router.POST("/public/v1/portal/tickets", h.CreateTicket)
+	router.GET("/public/v1/portal/returns", h.ListReturns)
+	router.POST("/public/v1/portal/returns", h.CreateReturn)
+}
+
+// ListAccounts handles GET /api/v1/portal-accounts
+func (h *PortalHandler) ListAccounts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.service.ListAccounts(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// InviteAccount handles POST /api/v1/portal-accounts, inviting a contact to
+// the portal
+func (h *PortalHandler) InviteAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.InviteAccount(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, account)
+}
+
+// ResendInvite handles POST /api/v1/portal-accounts/:id/invite
+func (h *PortalHandler) ResendInvite(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.ResendInvite(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, account)
+}
+
+// DisableAccount handles POST /api/v1/portal-accounts/:id/disable
+func (h *PortalHandler) DisableAccount(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.service.DisableAccount(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, account)
+}
+
+// ListSupportTickets handles GET /api/v1/support-tickets with optional
+// contact_id, status, open, limit and offset filters
+func (h *PortalHandler) ListSupportTickets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.TicketFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.TicketStatus(q.Get("status")),
+		OpenOnly:       q.Get("open") == "true",
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("contact_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+			return
+		}
+		filter.ContactID = &id
+	}
+
+	tickets, err := h.service.ListSupportTickets(r.Context(), filter)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tickets)
+}
+
+// SetTicketStatus handles PUT /api/v1/support-tickets/:id/status
+func (h *PortalHandler) SetTicketStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.TicketStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := h.service.SetTicketStatus(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ticket)
+}
+
+// ListReturnRequests handles GET /api/v1/return-requests with optional
+// contact_id, status, limit and offset filters
+func (h *PortalHandler) ListReturnRequests(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.ReturnFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.ReturnStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("contact_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+			return
+		}
+		filter.ContactID = &id
+	}
+
+	returns, err := h.service.ListReturnRequests(r.Context(), filter)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, returns)
+}
+
+// SetReturnStatus handles PUT /api/v1/return-requests/:id/status
+func (h *PortalHandler) SetReturnStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid return request ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ReturnStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.service.SetReturnStatus(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// RequestMagicLink handles POST /public/v1/portal/auth/magic-link. It is
+// accepted whether or not the email belongs to an account.
+func (h *PortalHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req types.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RequestMagicLink(r.Context(), req); err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RedeemMagicLink handles POST /public/v1/portal/auth/magic-link/redeem,
+// exchanging a sign-in link's token for a session
+func (h *PortalHandler) RedeemMagicLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req types.TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.service.RedeemMagicLink(r.Context(), req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, session)
+}
+
+// Login handles POST /public/v1/portal/auth/login
+func (h *PortalHandler) Login(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req types.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.service.Login(r.Context(), req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, session)
+}
+
+// Logout handles POST /public/v1/portal/auth/logout, ending the session
+func (h *PortalHandler) Logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	token, ok := bearerToken(r)
+	if !ok {
+		writePortalError(w, service.ErrUnauthorized)
+		return
+	}
+
+	if err := h.service.SignOut(r.Context(), token); err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Account handles GET /public/v1/portal/account
+func (h *PortalHandler) Account(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, customer)
+}
+
+// SetPassword handles PUT /public/v1/portal/account/password
+func (h *PortalHandler) SetPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.PasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetPassword(r.Context(), *customer, req); err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListQuotes handles GET /public/v1/portal/quotes
+func (h *PortalHandler) ListQuotes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	quotes, err := h.service.ListQuotes(r.Context(), *customer, queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quotes)
+}
+
+// ListOrders handles GET /public/v1/portal/orders
+func (h *PortalHandler) ListOrders(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	orders, err := h.service.ListOrders(r.Context(), *customer, queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orders)
+}
+
+// GetOrder handles GET /public/v1/portal/quotes/:id and
+// GET /public/v1/portal/orders/:id
+func (h *PortalHandler) GetOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.GetOrder(r.Context(), *customer, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// ApproveQuote handles POST /public/v1/portal/quotes/:id/approve
+func (h *PortalHandler) ApproveQuote(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid quote ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.ApproveQuote(r.Context(), *customer, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// ListInvoices handles GET /public/v1/portal/invoices
+func (h *PortalHandler) ListInvoices(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	invoices, err := h.service.ListInvoices(r.Context(), *customer, queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invoices)
+}
+
+// GetInvoice handles GET /public/v1/portal/invoices/:id
+func (h *PortalHandler) GetInvoice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.service.GetInvoice(r.Context(), *customer, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invoice)
+}
+
+// InvoicePDF handles GET /public/v1/portal/invoices/:id/pdf
+func (h *PortalHandler) InvoicePDF(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	pdf, filename, err := h.service.InvoicePDF(r.Context(), *customer, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdf)
+}
+
+// ListShipments handles GET /public/v1/portal/shipments
+func (h *PortalHandler) ListShipments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	shipments, err := h.service.ListShipments(r.Context(), *customer, queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shipments)
+}
+
+// TrackShipment handles GET /public/v1/portal/shipments/:id/tracking
+func (h *PortalHandler) TrackShipment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipment ID", http.StatusBadRequest)
+		return
+	}
+
+	tracking, err := h.service.TrackShipment(r.Context(), *customer, id)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tracking)
+}
+
+// ListTickets handles GET /public/v1/portal/tickets. Only open tickets are
+// listed unless all=true.
+func (h *PortalHandler) ListTickets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	tickets, err := h.service.ListTickets(r.Context(), *customer, q.Get("all") == "true",
+		queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tickets)
+}
+
+// CreateTicket handles POST /public/v1/portal/tickets
+func (h *PortalHandler) CreateTicket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.TicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := h.service.CreateTicket(r.Context(), *customer, req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ticket)
+}
+
+// ListReturns handles GET /public/v1/portal/returns
+func (h *PortalHandler) ListReturns(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	returns, err := h.service.ListReturns(r.Context(), *customer, queryInt(q.Get("limit")), queryInt(q.Get("offset")))
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, returns)
+}
+
+// CreateReturn handles POST /public/v1/portal/returns
+func (h *PortalHandler) CreateReturn(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	customer, ok := h.requireCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.service.CreateReturn(r.Context(), *customer, req)
+	if err != nil {
+		writePortalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// requireCustomer authenticates the portal session token of the request,
+// writing 401 when there is none or it is no longer valid
+func (h *PortalHandler) requireCustomer(w http.ResponseWriter, r *http.Request) (*types.Customer, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		writePortalError(w, service.ErrUnauthorized)
+		return nil, false
+	}
+	customer, err := h.service.Authenticate(r.Context(), token)
+	if err != nil {
+		writePortalError(w, err)
+		return nil, false
+	}
+	return customer, true
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writePortalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState),
+		errors.Is(err, repository.ErrNotReturnable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrPDFUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
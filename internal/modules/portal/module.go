@@ -0,0 +1,100 @@
+package portal
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PortalModule represents the customer self-service portal module
+type PortalModule struct {
+	portalService *service.PortalService
+	invoiceSource *service.InvoiceSource
+	portalHandler *handler.PortalHandler
+	logger        *slog.Logger
+}
+
+// NewPortalModule creates a new customer portal module
+func NewPortalModule() *PortalModule {
+	return &PortalModule{}
+}
+
+// Name returns the module name
+func (m *PortalModule) Name() string {
+	return "portal"
+}
+
+// InvoiceSource returns the data source of invoice document templates, so
+// the back office prints invoices with the same template data as the portal
+func (m *PortalModule) InvoiceSource() *service.InvoiceSource {
+	return m.invoiceSource
+}
+
+// SetMailer emails sign-in links to customers. It must be called after Init.
+func (m *PortalModule) SetMailer(mailer service.Mailer) {
+	if m.portalService != nil {
+		m.portalService.SetMailer(mailer)
+	}
+}
+
+// SetPortalURL sets the portal address sign-in links open. It must be
+// called after Init.
+func (m *PortalModule) SetPortalURL(url string) {
+	if m.portalService != nil {
+		m.portalService.SetPortalURL(url)
+	}
+}
+
+// SetRenderer renders invoice PDFs from the organization's invoice
+// template. It must be called after Init.
+func (m *PortalModule) SetRenderer(renderer service.DocumentRenderer) {
+	if m.portalService != nil {
+		m.portalService.SetRenderer(renderer)
+	}
+}
+
+// Init initializes the customer portal module
+func (m *PortalModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "portal")
+	m.logger.Info("Initializing portal module")
+
+	// Create repositories
+	portalRepo := repository.NewPortalRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.portalService = service.NewPortalService(portalRepo, authAdapter, deps.EventBus, m.logger)
+	m.invoiceSource = service.NewInvoiceSource(portalRepo)
+
+	// Create handlers
+	m.portalHandler = handler.NewPortalHandler(m.portalService, m.logger)
+
+	m.logger.Info("Portal module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers portal module routes
+func (m *PortalModule) RegisterRoutes(router interface{}) {
+	if m.portalHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.portalHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the portal module
+func (m *PortalModule) RegisterEventHandlers(bus interface{}) {
+	// The portal only publishes events
+}
+
+// Health checks the health of the portal module
+func (m *PortalModule) Health() error {
+	return nil
+}
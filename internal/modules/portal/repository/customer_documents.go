@@ -0,0 +1,401 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+)
+
+// customerContacts selects the contacts whose documents a customer sees:
+// its contact and the contact's child contacts, such as the people and
+// addresses of a customer company. orgParam and contactParam number the
+// placeholders of the organization and the customer's contact.
+func customerContacts(orgParam, contactParam int) string {
+	return fmt.Sprintf(`SELECT cc.id FROM contacts cc
+		WHERE cc.organization_id = $%d AND (cc.id = $%d OR cc.parent_id = $%d)`, orgParam, contactParam, contactParam)
+}
+
+// Quotes are orders sent to the customer; drafts are not shown until sent
+const (
+	quoteStates = `('sent')`
+	orderStates = `('sale', 'done')`
+)
+
+const orderColumns = `o.id, o.name, COALESCE(o.client_order_ref, ''), o.state, o.date_order, o.validity_date,
+	o.confirmation_date, COALESCE(o.invoice_status, 'no'), COALESCE(o.delivery_status, 'no'),
+	COALESCE(o.amount_untaxed, 0), COALESCE(o.amount_tax, 0), COALESCE(o.amount_total, 0), COALESCE(o.note, '')`
+
+func scanOrder(row rowScanner) (*types.Order, error) {
+	var o types.Order
+	var validityDate, confirmationDate sql.NullTime
+	if err := row.Scan(&o.ID, &o.Reference, &o.CustomerRef, &o.State, &o.OrderDate, &validityDate, &confirmationDate,
+		&o.InvoiceStatus, &o.DeliveryStatus, &o.AmountUntaxed, &o.AmountTax, &o.AmountTotal, &o.Note); err != nil {
+		return nil, err
+	}
+	o.ValidityDate = nullTime(validityDate)
+	o.ConfirmationDate = nullTime(confirmationDate)
+	return &o, nil
+}
+
+// ListOrders returns the customer's quotes, or its confirmed orders, latest
+// first and without lines
+func (r *PortalRepository) ListOrders(ctx context.Context, customer types.Customer, quotes bool, limit, offset int) ([]types.Order, error) {
+	states := orderStates
+	if quotes {
+		states = quoteStates
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT `+orderColumns+` FROM sales_orders o
+		WHERE o.organization_id = $1 AND o.partner_id IN (`+customerContacts(1, 2)+`)
+			AND o.state IN `+states+` AND o.deleted_at IS NULL
+		ORDER BY o.date_order DESC, o.id
+		LIMIT $3 OFFSET $4`, customer.OrganizationID, customer.ContactID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []types.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+// FindOrder returns one of the customer's sent quotes or confirmed orders
+// with its product lines
+func (r *PortalRepository) FindOrder(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Order, error) {
+	order, err := scanOrder(r.db.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM sales_orders o
+		WHERE o.organization_id = $1 AND o.partner_id IN (`+customerContacts(1, 2)+`)
+			AND o.id = $3 AND o.state IN ('sent', 'sale', 'done') AND o.deleted_at IS NULL`,
+		customer.OrganizationID, customer.ContactID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("order %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, product_id, COALESCE(product_uom_qty, 0), COALESCE(qty_delivered, 0),
+			COALESCE(price_unit, 0), COALESCE(discount, 0), COALESCE(price_subtotal, 0), COALESCE(price_total, 0)
+		FROM sales_order_lines
+		WHERE order_id = $1 AND deleted_at IS NULL AND display_type IS NULL
+		ORDER BY sequence, id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order lines: %w", err)
+	}
+	defer rows.Close()
+
+	order.Lines = []types.OrderLine{}
+	for rows.Next() {
+		var l types.OrderLine
+		var productID uuid.NullUUID
+		if err := rows.Scan(&l.ID, &l.Name, &productID, &l.Quantity, &l.QuantityDelivered, &l.PriceUnit, &l.Discount,
+			&l.PriceSubtotal, &l.PriceTotal); err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		l.ProductID = nullUUID(productID)
+		order.Lines = append(order.Lines, l)
+	}
+	return order, rows.Err()
+}
+
+// ApproveQuote confirms a sent quote of the customer as a sales order. The
+// quote must still be sent and within its validity date.
+func (r *PortalRepository) ApproveQuote(ctx context.Context, customer types.Customer, id uuid.UUID, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE sales_orders o SET
+			state = 'sale',
+			confirmation_date = $4,
+			quote_accepted_at = $4,
+			metadata = COALESCE(o.metadata, '{}'::jsonb) || jsonb_build_object('approved_by_portal_account', $5::text),
+			updated_at = $4
+		WHERE o.organization_id = $1 AND o.partner_id IN (`+customerContacts(1, 2)+`)
+			AND o.id = $3 AND o.state = 'sent' AND o.deleted_at IS NULL
+			AND (o.validity_date IS NULL OR o.validity_date >= $4::date)
+	`, customer.OrganizationID, customer.ContactID, id, at, customer.AccountID.String())
+	if err != nil {
+		return fmt.Errorf("failed to approve quote: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("quote can no longer be approved: %w", ErrInvalidState)
+	}
+	return nil
+}
+
+const invoiceColumns = `i.id, COALESCE(i.name, ''), i.move_type, i.invoice_date, i.invoice_date_due,
+	COALESCE(i.payment_state, 'not_paid'), COALESCE(i.invoice_origin, ''), COALESCE(i.amount_untaxed, 0),
+	COALESCE(i.amount_tax, 0), COALESCE(i.amount_total, 0), COALESCE(i.amount_residual, 0)`
+
+// Customers see their posted invoices and credit notes
+var invoiceWhere = `i.organization_id = $1 AND i.partner_id IN (` + customerContacts(1, 2) + `)
+	AND i.move_type IN ('out_invoice', 'out_refund') AND i.state = 'posted' AND i.deleted_at IS NULL`
+
+func scanInvoice(row rowScanner) (*types.Invoice, error) {
+	var i types.Invoice
+	var invoiceDate, dueDate sql.NullTime
+	if err := row.Scan(&i.ID, &i.Number, &i.MoveType, &invoiceDate, &dueDate, &i.PaymentState, &i.Origin,
+		&i.AmountUntaxed, &i.AmountTax, &i.AmountTotal, &i.AmountResidual); err != nil {
+		return nil, err
+	}
+	i.InvoiceDate = nullTime(invoiceDate)
+	i.DueDate = nullTime(dueDate)
+	return &i, nil
+}
+
+// ListInvoices returns the customer's posted invoices and credit notes,
+// latest first and without lines
+func (r *PortalRepository) ListInvoices(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Invoice, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+invoiceColumns+` FROM invoices i
+		WHERE `+invoiceWhere+`
+		ORDER BY COALESCE(i.invoice_date, i.date) DESC, i.id
+		LIMIT $3 OFFSET $4`, customer.OrganizationID, customer.ContactID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []types.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// FindInvoice returns one of the customer's posted invoices with its product
+// lines; tax and receivable lines are left out
+func (r *PortalRepository) FindInvoice(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Invoice, error) {
+	invoice, err := scanInvoice(r.db.QueryRowContext(ctx, `SELECT `+invoiceColumns+` FROM invoices i
+		WHERE `+invoiceWhere+` AND i.id = $3`, customer.OrganizationID, customer.ContactID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("invoice %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invoice: %w", err)
+	}
+
+	if invoice.Lines, err = r.invoiceLines(ctx, id); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// invoiceLines returns the product lines of an invoice; tax and receivable
+// lines are left out
+func (r *PortalRepository) invoiceLines(ctx context.Context, id uuid.UUID) ([]types.InvoiceLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(name, ''), COALESCE(quantity, 0), COALESCE(price_unit, 0), COALESCE(discount, 0),
+			COALESCE(price_subtotal, 0), COALESCE(price_total, 0)
+		FROM invoice_lines
+		WHERE move_id = $1 AND tax_line_id IS NULL AND COALESCE(price_subtotal, 0) <> 0
+		ORDER BY sequence, id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoice lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []types.InvoiceLine{}
+	for rows.Next() {
+		var l types.InvoiceLine
+		if err := rows.Scan(&l.Name, &l.Quantity, &l.PriceUnit, &l.Discount, &l.PriceSubtotal, &l.PriceTotal); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// Posted invoices and credit notes, whoever they were sent to
+const postedInvoiceWhere = `i.organization_id = $1
+	AND i.move_type IN ('out_invoice', 'out_refund') AND i.state = 'posted' AND i.deleted_at IS NULL`
+
+// FindPostedInvoice returns a posted invoice of the organization with its
+// product lines and the customer it was sent to, for printing in the back
+// office
+func (r *PortalRepository) FindPostedInvoice(ctx context.Context, orgID, id uuid.UUID) (*types.Invoice, *types.Customer, error) {
+	customer := types.Customer{OrganizationID: orgID}
+	var i types.Invoice
+	var invoiceDate, dueDate sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT `+invoiceColumns+`,
+			c.id, COALESCE(c.display_name, c.name, ''), COALESCE(c.email, '')
+		FROM invoices i
+		JOIN contacts c ON c.id = i.partner_id
+		WHERE `+postedInvoiceWhere+` AND i.id = $2`, orgID, id).Scan(
+		&i.ID, &i.Number, &i.MoveType, &invoiceDate, &dueDate, &i.PaymentState, &i.Origin,
+		&i.AmountUntaxed, &i.AmountTax, &i.AmountTotal, &i.AmountResidual,
+		&customer.ContactID, &customer.ContactName, &customer.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("invoice %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find invoice: %w", err)
+	}
+	i.InvoiceDate = nullTime(invoiceDate)
+	i.DueDate = nullTime(dueDate)
+
+	if i.Lines, err = r.invoiceLines(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	return &i, &customer, nil
+}
+
+// ListPostedInvoices returns the organization's posted invoices and credit
+// notes dated in [from, to), oldest first and without lines
+func (r *PortalRepository) ListPostedInvoices(ctx context.Context, orgID uuid.UUID, from, to *time.Time, limit int) ([]types.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices i WHERE ` + postedInvoiceWhere
+	args := []interface{}{orgID}
+	if from != nil {
+		args = append(args, *from)
+		query += fmt.Sprintf(" AND COALESCE(i.invoice_date, i.date) >= $%d", len(args))
+	}
+	if to != nil {
+		args = append(args, *to)
+		query += fmt.Sprintf(" AND COALESCE(i.invoice_date, i.date) < $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY COALESCE(i.invoice_date, i.date), i.name, i.id LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []types.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+const shipmentColumns = `s.id, p.name, COALESCE(p.origin, ''), COALESCE(s.tracking_number, ''),
+	COALESCE(s.carrier_name, ''), s.status, s.estimated_arrival_at, s.departed_at, s.arrived_at, s.last_event_at,
+	s.route_id, s.last_latitude, s.last_longitude`
+
+// Customers see outbound shipments of pickings to them once scheduled
+var shipmentWhere = `s.organization_id = $1 AND p.partner_id IN (` + customerContacts(1, 2) + `)
+	AND s.shipment_type = 'outbound' AND s.status <> 'draft' AND s.deleted_at IS NULL`
+
+func scanShipment(row rowScanner) (*types.Shipment, error) {
+	var s types.Shipment
+	var estimatedArrivalAt, departedAt, arrivedAt, lastEventAt sql.NullTime
+	var routeID uuid.NullUUID
+	var latitude, longitude sql.NullFloat64
+	if err := row.Scan(&s.ID, &s.Reference, &s.Origin, &s.TrackingNumber, &s.CarrierName, &s.Status,
+		&estimatedArrivalAt, &departedAt, &arrivedAt, &lastEventAt, &routeID, &latitude, &longitude); err != nil {
+		return nil, err
+	}
+	if latitude.Valid && longitude.Valid && lastEventAt.Valid {
+		s.LastPosition = &types.Position{Latitude: latitude.Float64, Longitude: longitude.Float64, RecordedAt: lastEventAt.Time}
+	}
+	s.EstimatedArrivalAt = nullTime(estimatedArrivalAt)
+	s.DepartedAt = nullTime(departedAt)
+	s.ArrivedAt = nullTime(arrivedAt)
+	s.LastEventAt = nullTime(lastEventAt)
+	s.RouteID = nullUUID(routeID)
+	return &s, nil
+}
+
+// ListShipments returns the customer's shipments, latest first
+func (r *PortalRepository) ListShipments(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Shipment, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+shipmentColumns+`
+		FROM delivery_shipments s JOIN stock_pickings p ON p.id = s.picking_id
+		WHERE `+shipmentWhere+`
+		ORDER BY s.created_at DESC, s.id
+		LIMIT $3 OFFSET $4`, customer.OrganizationID, customer.ContactID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipments: %w", err)
+	}
+	defer rows.Close()
+
+	shipments := []types.Shipment{}
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		shipments = append(shipments, *shipment)
+	}
+	return shipments, rows.Err()
+}
+
+// FindShipment returns one of the customer's shipments
+func (r *PortalRepository) FindShipment(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Shipment, error) {
+	shipment, err := scanShipment(r.db.QueryRowContext(ctx, `SELECT `+shipmentColumns+`
+		FROM delivery_shipments s JOIN stock_pickings p ON p.id = s.picking_id
+		WHERE `+shipmentWhere+` AND s.id = $3`, customer.OrganizationID, customer.ContactID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("shipment %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shipment: %w", err)
+	}
+	return shipment, nil
+}
+
+// ListTrackingEvents returns a shipment's tracking events in the order they
+// happened
+func (r *PortalRepository) ListTrackingEvents(ctx context.Context, orgID, shipmentID uuid.UUID) ([]types.TrackingEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT event_type, COALESCE(status, ''), COALESCE(message, ''), event_time, latitude, longitude
+		FROM delivery_tracking_events
+		WHERE organization_id = $1 AND shipment_id = $2
+		ORDER BY event_time, id
+	`, orgID, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracking events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []types.TrackingEvent{}
+	for rows.Next() {
+		var e types.TrackingEvent
+		var latitude, longitude sql.NullFloat64
+		if err := rows.Scan(&e.EventType, &e.Status, &e.Message, &e.EventTime, &latitude, &longitude); err != nil {
+			return nil, fmt.Errorf("failed to scan tracking event: %w", err)
+		}
+		e.Latitude = nullFloat(latitude)
+		e.Longitude = nullFloat(longitude)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// LatestRoutePosition returns the last position recorded for a delivery
+// route, or nil when none was recorded
+func (r *PortalRepository) LatestRoutePosition(ctx context.Context, orgID, routeID uuid.UUID) (*types.Position, error) {
+	var p types.Position
+	err := r.db.QueryRowContext(ctx, `
+		SELECT latitude, longitude, recorded_at FROM delivery_route_positions
+		WHERE organization_id = $1 AND route_id = $2
+		ORDER BY recorded_at DESC
+		LIMIT 1
+	`, orgID, routeID).Scan(&p.Latitude, &p.Longitude, &p.RecordedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find route position: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,681 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when an account, document, ticket or return
+	// does not exist or is not the customer's
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a contact or email already has an account
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when a record's status does not allow the
+	// change, such as approving a quote that was already confirmed
+	ErrInvalidState = errors.New("state does not allow this")
+	// ErrNotReturnable is returned when a return asks for more than was
+	// delivered and not already returned
+	ErrNotReturnable = errors.New("exceeds the quantity that can be returned")
+)
+
+// PortalRepo defines the interface for portal repository operations
+type PortalRepo interface {
+	FindOrganizationID(ctx context.Context, slug string) (uuid.UUID, error)
+	FindContact(ctx context.Context, orgID, contactID uuid.UUID) (name, email string, err error)
+	ListAccounts(ctx context.Context, orgID uuid.UUID) ([]types.Account, error)
+	FindAccount(ctx context.Context, orgID, id uuid.UUID) (*types.Account, error)
+	FindAccountByEmail(ctx context.Context, orgID uuid.UUID, email string) (*types.Account, error)
+	CreateAccount(ctx context.Context, account types.Account) (*types.Account, error)
+	SetAccountStatus(ctx context.Context, orgID, id uuid.UUID, status types.AccountStatus, at time.Time) (*types.Account, error)
+	SetPassword(ctx context.Context, orgID, accountID uuid.UUID, hash string, at time.Time) error
+	CreateMagicLink(ctx context.Context, link types.MagicLink) error
+	RedeemMagicLink(ctx context.Context, tokenHash string, at time.Time) (*types.Account, error)
+	CreateSession(ctx context.Context, account types.Account, tokenHash string, expiresAt, at time.Time) error
+	FindSession(ctx context.Context, tokenHash string, at time.Time) (*types.Customer, error)
+	RevokeSession(ctx context.Context, tokenHash string, at time.Time) error
+
+	ListOrders(ctx context.Context, customer types.Customer, quotes bool, limit, offset int) ([]types.Order, error)
+	FindOrder(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Order, error)
+	ApproveQuote(ctx context.Context, customer types.Customer, id uuid.UUID, at time.Time) error
+	ListInvoices(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Invoice, error)
+	FindInvoice(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Invoice, error)
+	ListShipments(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Shipment, error)
+	FindShipment(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Shipment, error)
+	ListTrackingEvents(ctx context.Context, orgID, shipmentID uuid.UUID) ([]types.TrackingEvent, error)
+	LatestRoutePosition(ctx context.Context, orgID, routeID uuid.UUID) (*types.Position, error)
+
+	ListTickets(ctx context.Context, filter types.TicketFilter) ([]types.Ticket, error)
+	FindTicket(ctx context.Context, orgID, id uuid.UUID) (*types.Ticket, error)
+	CreateTicket(ctx context.Context, ticket types.Ticket) (*types.Ticket, error)
+	SetTicketStatus(ctx context.Context, orgID, id uuid.UUID, status types.TicketStatus, userID *uuid.UUID, at time.Time) (*types.Ticket, error)
+	ListReturns(ctx context.Context, filter types.ReturnFilter) ([]types.Return, error)
+	FindReturn(ctx context.Context, orgID, id uuid.UUID) (*types.Return, error)
+	CreateReturn(ctx context.Context, customer types.Customer, ret types.Return) (*types.Return, error)
+	SetReturnStatus(ctx context.Context, orgID, id uuid.UUID, from, to types.ReturnStatus, userID *uuid.UUID, at time.Time) (*types.Return, error)
+}
+
+// PortalRepository stores portal accounts, sessions, support tickets and
+// return requests, and reads the customer's sales documents
+type PortalRepository struct {
+	db *sql.DB
+}
+
+// Ensure PortalRepository implements PortalRepo interface
+var _ PortalRepo = &PortalRepository{}
+
+func NewPortalRepository(db *sql.DB) *PortalRepository {
+	return &PortalRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// FindOrganizationID returns the ID of the organization with the slug
+func (r *PortalRepository) FindOrganizationID(ctx context.Context, slug string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM organizations WHERE slug = $1 AND deleted_at IS NULL`, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("organization %w", ErrNotFound)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+	return id, nil
+}
+
+// FindContact returns a contact's name and email
+func (r *PortalRepository) FindContact(ctx context.Context, orgID, contactID uuid.UUID) (string, string, error) {
+	var name, email string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, COALESCE(email, '') FROM contacts
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+	`, contactID, orgID).Scan(&name, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", fmt.Errorf("contact %s %w", contactID, ErrNotFound)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find contact: %w", err)
+	}
+	return name, email, nil
+}
+
+const accountColumns = `a.id, a.organization_id, a.contact_id, c.name, a.email, a.status, COALESCE(a.password_hash, ''),
+	a.last_login_at, a.created_at, a.updated_at, a.created_by`
+
+const accountFrom = `portal_accounts a JOIN contacts c ON c.id = a.contact_id`
+
+func scanAccount(row rowScanner) (*types.Account, error) {
+	var a types.Account
+	var lastLoginAt sql.NullTime
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&a.ID, &a.OrganizationID, &a.ContactID, &a.ContactName, &a.Email, &a.Status, &a.PasswordHash,
+		&lastLoginAt, &a.CreatedAt, &a.UpdatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	a.HasPassword = a.PasswordHash != ""
+	a.LastLoginAt = nullTime(lastLoginAt)
+	a.CreatedBy = nullUUID(createdBy)
+	return &a, nil
+}
+
+func (r *PortalRepository) findAccount(ctx context.Context, where string, args ...interface{}) (*types.Account, error) {
+	account, err := scanAccount(r.db.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM `+accountFrom+` WHERE `+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("portal account %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find portal account: %w", err)
+	}
+	return account, nil
+}
+
+// ListAccounts returns the organization's portal accounts by email
+func (r *PortalRepository) ListAccounts(ctx context.Context, orgID uuid.UUID) ([]types.Account, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+accountColumns+` FROM `+accountFrom+`
+		WHERE a.organization_id = $1
+		ORDER BY lower(a.email)`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portal accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []types.Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portal account: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+	return accounts, rows.Err()
+}
+
+// FindAccount returns a portal account
+func (r *PortalRepository) FindAccount(ctx context.Context, orgID, id uuid.UUID) (*types.Account, error) {
+	return r.findAccount(ctx, `a.id = $1 AND a.organization_id = $2`, id, orgID)
+}
+
+// FindAccountByEmail returns the organization's portal account with the
+// email, whatever its case
+func (r *PortalRepository) FindAccountByEmail(ctx context.Context, orgID uuid.UUID, email string) (*types.Account, error) {
+	return r.findAccount(ctx, `a.organization_id = $1 AND lower(a.email) = lower($2)`, orgID, email)
+}
+
+// CreateAccount creates a portal account
+func (r *PortalRepository) CreateAccount(ctx context.Context, account types.Account) (*types.Account, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO portal_accounts (id, organization_id, contact_id, email, status, created_at, updated_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+	`, account.ID, account.OrganizationID, account.ContactID, account.Email, account.Status, account.CreatedAt, account.CreatedBy)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("a portal account for this contact or email %w", ErrDuplicate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create portal account: %w", err)
+	}
+	return r.FindAccount(ctx, account.OrganizationID, account.ID)
+}
+
+// SetAccountStatus changes an account's status. Disabling an account ends
+// its sessions and voids its unused sign-in links.
+func (r *PortalRepository) SetAccountStatus(ctx context.Context, orgID, id uuid.UUID, status types.AccountStatus, at time.Time) (*types.Account, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE portal_accounts SET status = $3, updated_at = $4
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID, status, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update portal account: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("portal account %w", ErrNotFound)
+	}
+
+	if status == types.AccountDisabled {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE portal_sessions SET revoked_at = $2 WHERE account_id = $1 AND revoked_at IS NULL
+		`, id, at); err != nil {
+			return nil, fmt.Errorf("failed to end portal sessions: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE portal_magic_links SET used_at = $2 WHERE account_id = $1 AND used_at IS NULL
+		`, id, at); err != nil {
+			return nil, fmt.Errorf("failed to void sign-in links: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit portal account: %w", err)
+	}
+	return r.FindAccount(ctx, orgID, id)
+}
+
+// SetPassword stores the hash of an account's password
+func (r *PortalRepository) SetPassword(ctx context.Context, orgID, accountID uuid.UUID, hash string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE portal_accounts SET password_hash = $3, updated_at = $4
+		WHERE id = $1 AND organization_id = $2
+	`, accountID, orgID, hash, at)
+	if err != nil {
+		return fmt.Errorf("failed to set portal password: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("portal account %w", ErrNotFound)
+	}
+	return nil
+}
+
+// CreateMagicLink stores a sign-in link
+func (r *PortalRepository) CreateMagicLink(ctx context.Context, link types.MagicLink) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO portal_magic_links (organization_id, account_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, link.OrganizationID, link.AccountID, link.TokenHash, link.ExpiresAt, link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sign-in link: %w", err)
+	}
+	return nil
+}
+
+// RedeemMagicLink uses up an unexpired sign-in link and returns its account,
+// activating an invited account. Links of disabled accounts are not redeemed.
+func (r *PortalRepository) RedeemMagicLink(ctx context.Context, tokenHash string, at time.Time) (*types.Account, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orgID, accountID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		UPDATE portal_magic_links l SET used_at = $2
+		FROM portal_accounts a
+		WHERE l.token_hash = $1 AND l.used_at IS NULL AND l.expires_at > $2
+			AND a.id = l.account_id AND a.status <> 'disabled'
+		RETURNING l.organization_id, l.account_id
+	`, tokenHash, at).Scan(&orgID, &accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sign-in link %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem sign-in link: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE portal_accounts SET status = 'active', updated_at = $2 WHERE id = $1 AND status = 'invited'
+	`, accountID, at); err != nil {
+		return nil, fmt.Errorf("failed to activate portal account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sign-in: %w", err)
+	}
+	return r.FindAccount(ctx, orgID, accountID)
+}
+
+// CreateSession starts a session of the account and records the sign-in
+func (r *PortalRepository) CreateSession(ctx context.Context, account types.Account, tokenHash string, expiresAt, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO portal_sessions (organization_id, account_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, account.OrganizationID, account.ID, tokenHash, expiresAt, at); err != nil {
+		return fmt.Errorf("failed to create portal session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE portal_accounts SET last_login_at = $2 WHERE id = $1
+	`, account.ID, at); err != nil {
+		return fmt.Errorf("failed to record portal sign-in: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit portal session: %w", err)
+	}
+	return nil
+}
+
+// FindSession returns the customer of an unexpired, unrevoked session of an
+// active account
+func (r *PortalRepository) FindSession(ctx context.Context, tokenHash string, at time.Time) (*types.Customer, error) {
+	var c types.Customer
+	err := r.db.QueryRowContext(ctx, `
+		SELECT a.id, a.organization_id, a.contact_id, ct.name, a.email, a.password_hash IS NOT NULL
+		FROM portal_sessions s
+		JOIN portal_accounts a ON a.id = s.account_id
+		JOIN contacts ct ON ct.id = a.contact_id
+		WHERE s.token_hash = $1 AND s.revoked_at IS NULL AND s.expires_at > $2 AND a.status = 'active'
+	`, tokenHash, at).Scan(&c.AccountID, &c.OrganizationID, &c.ContactID, &c.ContactName, &c.Email, &c.HasPassword)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("portal session %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find portal session: %w", err)
+	}
+	return &c, nil
+}
+
+// RevokeSession ends a session
+func (r *PortalRepository) RevokeSession(ctx context.Context, tokenHash string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE portal_sessions SET revoked_at = $2 WHERE token_hash = $1 AND revoked_at IS NULL
+	`, tokenHash, at); err != nil {
+		return fmt.Errorf("failed to end portal session: %w", err)
+	}
+	return nil
+}
+
+const ticketColumns = `id, organization_id, contact_id, portal_account_id, subject, description, status, priority,
+	resolved_at, created_at, updated_at`
+
+func scanTicket(row rowScanner) (*types.Ticket, error) {
+	var t types.Ticket
+	var accountID uuid.NullUUID
+	var resolvedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.OrganizationID, &t.ContactID, &accountID, &t.Subject, &t.Description, &t.Status,
+		&t.Priority, &resolvedAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.PortalAccountID = nullUUID(accountID)
+	t.ResolvedAt = nullTime(resolvedAt)
+	return &t, nil
+}
+
+// ListTickets returns the tickets matching the filter, latest first
+func (r *PortalRepository) ListTickets(ctx context.Context, filter types.TicketFilter) ([]types.Ticket, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.ContactID != nil {
+		args = append(args, *filter.ContactID)
+		where = append(where, fmt.Sprintf("contact_id IN (%s)", customerContacts(1, len(args))))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.OpenOnly {
+		where = append(where, "status IN ('open', 'pending')")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+ticketColumns+` FROM support_tickets
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list support tickets: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []types.Ticket{}
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan support ticket: %w", err)
+		}
+		tickets = append(tickets, *ticket)
+	}
+	return tickets, rows.Err()
+}
+
+// FindTicket returns a support ticket
+func (r *PortalRepository) FindTicket(ctx context.Context, orgID, id uuid.UUID) (*types.Ticket, error) {
+	ticket, err := scanTicket(r.db.QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM support_tickets
+		WHERE id = $1 AND organization_id = $2`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("support ticket %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find support ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// CreateTicket creates a support ticket
+func (r *PortalRepository) CreateTicket(ctx context.Context, ticket types.Ticket) (*types.Ticket, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO support_tickets (
+			id, organization_id, contact_id, portal_account_id, subject, description, status, priority,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+	`, ticket.ID, ticket.OrganizationID, ticket.ContactID, ticket.PortalAccountID, ticket.Subject, ticket.Description,
+		ticket.Status, ticket.Priority, ticket.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create support ticket: %w", err)
+	}
+	return r.FindTicket(ctx, ticket.OrganizationID, ticket.ID)
+}
+
+// SetTicketStatus moves a ticket to a status. Resolving or closing a ticket
+// records when it was resolved; reopening it clears that.
+func (r *PortalRepository) SetTicketStatus(ctx context.Context, orgID, id uuid.UUID, status types.TicketStatus, userID *uuid.UUID, at time.Time) (*types.Ticket, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE support_tickets SET
+			status = $3,
+			resolved_at = CASE WHEN $3 IN ('resolved', 'closed') THEN COALESCE(resolved_at, $4) END,
+			updated_at = $4,
+			updated_by = $5
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID, status, at, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update support ticket: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("support ticket %w", ErrNotFound)
+	}
+	return r.FindTicket(ctx, orgID, id)
+}
+
+const returnColumns = `r.id, r.organization_id, r.contact_id, r.order_id, o.name, r.portal_account_id, r.reason, r.status,
+	r.created_at, r.updated_at`
+
+const returnFrom = `return_requests r JOIN sales_orders o ON o.id = r.order_id`
+
+func scanReturn(row rowScanner) (*types.Return, error) {
+	var ret types.Return
+	var accountID uuid.NullUUID
+	if err := row.Scan(&ret.ID, &ret.OrganizationID, &ret.ContactID, &ret.OrderID, &ret.OrderReference, &accountID,
+		&ret.Reason, &ret.Status, &ret.CreatedAt, &ret.UpdatedAt); err != nil {
+		return nil, err
+	}
+	ret.PortalAccountID = nullUUID(accountID)
+	ret.Lines = []types.ReturnLine{}
+	return &ret, nil
+}
+
+// ListReturns returns the return requests matching the filter, latest
+// first, with their lines
+func (r *PortalRepository) ListReturns(ctx context.Context, filter types.ReturnFilter) ([]types.Return, error) {
+	where := []string{"r.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.ContactID != nil {
+		args = append(args, *filter.ContactID)
+		where = append(where, fmt.Sprintf("r.contact_id IN (%s)", customerContacts(1, len(args))))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+returnColumns+` FROM `+returnFrom+`
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY r.created_at DESC, r.id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list return requests: %w", err)
+	}
+	defer rows.Close()
+
+	returns := []types.Return{}
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan return request: %w", err)
+		}
+		index[ret.ID] = len(returns)
+		returns = append(returns, *ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return returns, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(returns))
+	for _, ret := range returns {
+		ids = append(ids, ret.ID)
+	}
+	lines, err := r.returnLines(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, l := range lines {
+		returns[index[id]].Lines = l
+	}
+	return returns, nil
+}
+
+func (r *PortalRepository) returnLines(ctx context.Context, returnIDs []uuid.UUID) (map[uuid.UUID][]types.ReturnLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rl.return_id, rl.order_line_id, l.name, rl.quantity
+		FROM return_request_lines rl
+		JOIN sales_order_lines l ON l.id = rl.order_line_id
+		WHERE rl.return_id = ANY($1)
+		ORDER BY l.sequence, l.id
+	`, pq.Array(returnIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list return lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := make(map[uuid.UUID][]types.ReturnLine)
+	for rows.Next() {
+		var returnID uuid.UUID
+		var line types.ReturnLine
+		if err := rows.Scan(&returnID, &line.OrderLineID, &line.Name, &line.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan return line: %w", err)
+		}
+		lines[returnID] = append(lines[returnID], line)
+	}
+	return lines, rows.Err()
+}
+
+// FindReturn returns a return request with its lines
+func (r *PortalRepository) FindReturn(ctx context.Context, orgID, id uuid.UUID) (*types.Return, error) {
+	ret, err := scanReturn(r.db.QueryRowContext(ctx, `SELECT `+returnColumns+` FROM `+returnFrom+`
+		WHERE r.id = $1 AND r.organization_id = $2`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("return request %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find return request: %w", err)
+	}
+	lines, err := r.returnLines(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := lines[id]; ok {
+		ret.Lines = l
+	}
+	return ret, nil
+}
+
+// CreateReturn creates a return request for a confirmed order of the
+// customer. The order is locked while each line is checked against the
+// delivered quantity less what returns that were not rejected already take
+// back.
+func (r *PortalRepository) CreateReturn(ctx context.Context, customer types.Customer, ret types.Return) (*types.Return, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state string
+	err = tx.QueryRowContext(ctx, `
+		SELECT o.state FROM sales_orders o
+		WHERE o.organization_id = $1 AND o.partner_id IN (`+customerContacts(1, 2)+`)
+			AND o.id = $3 AND o.deleted_at IS NULL
+		FOR UPDATE
+	`, customer.OrganizationID, customer.ContactID, ret.OrderID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("order %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+	if state != "sale" && state != "done" {
+		return nil, fmt.Errorf("only confirmed orders can be returned: %w", ErrInvalidState)
+	}
+
+	for _, line := range ret.Lines {
+		var returnable float64
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(l.qty_delivered, 0) - COALESCE((
+				SELECT SUM(rl.quantity) FROM return_request_lines rl
+				JOIN return_requests rr ON rr.id = rl.return_id
+				WHERE rl.order_line_id = l.id AND rr.status <> 'rejected'
+			), 0)
+			FROM sales_order_lines l
+			WHERE l.id = $1 AND l.order_id = $2 AND l.deleted_at IS NULL
+		`, line.OrderLineID, ret.OrderID).Scan(&returnable)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("order line %s %w", line.OrderLineID, ErrNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check order line: %w", err)
+		}
+		if line.Quantity > returnable {
+			return nil, fmt.Errorf("order line %s: %g %w (%g)", line.OrderLineID, line.Quantity, ErrNotReturnable, returnable)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO return_requests (
+			id, organization_id, contact_id, order_id, portal_account_id, reason, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`, ret.ID, ret.OrganizationID, ret.ContactID, ret.OrderID, ret.PortalAccountID, ret.Reason, ret.Status,
+		ret.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create return request: %w", err)
+	}
+	for _, line := range ret.Lines {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO return_request_lines (return_id, order_line_id, quantity) VALUES ($1, $2, $3)
+		`, ret.ID, line.OrderLineID, line.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to create return line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit return request: %w", err)
+	}
+	return r.FindReturn(ctx, ret.OrganizationID, ret.ID)
+}
+
+// SetReturnStatus moves a return request from one status to another. The
+// request must still be in the from status.
+func (r *PortalRepository) SetReturnStatus(ctx context.Context, orgID, id uuid.UUID, from, to types.ReturnStatus, userID *uuid.UUID, at time.Time) (*types.Return, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE return_requests SET status = $4, updated_at = $5, updated_by = $6
+		WHERE id = $1 AND organization_id = $2 AND status = $3
+	`, id, orgID, from, to, at, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update return request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("return request is no longer %s: %w", from, ErrInvalidState)
+	}
+	return r.FindReturn(ctx, orgID, id)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+)
+
+// ListQuotes returns the customer's quotes awaiting approval
+func (s *PortalService) ListQuotes(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Order, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListOrders(ctx, customer, true, limit, offset)
+}
+
+// ListOrders returns the customer's confirmed orders
+func (s *PortalService) ListOrders(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Order, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListOrders(ctx, customer, false, limit, offset)
+}
+
+// GetOrder returns one of the customer's quotes or orders with its lines
+func (s *PortalService) GetOrder(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Order, error) {
+	return s.repo.FindOrder(ctx, customer, id)
+}
+
+// ApproveQuote confirms one of the customer's quotes as an order. Quotes
+// past their validity date can no longer be approved.
+func (s *PortalService) ApproveQuote(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Order, error) {
+	order, err := s.repo.FindOrder(ctx, customer, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.State != "sent" {
+		return nil, fmt.Errorf("only quotes awaiting approval can be approved, this one is %s: %w", order.State, repository.ErrInvalidState)
+	}
+	now := s.now()
+	if order.ValidityDate != nil && expired(*order.ValidityDate, now) {
+		return nil, fmt.Errorf("quote expired on %s: %w", order.ValidityDate.Format("2006-01-02"), repository.ErrInvalidState)
+	}
+
+	if err := s.repo.ApproveQuote(ctx, customer, id, now); err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventQuoteApproved, map[string]interface{}{
+		"organization_id":   customer.OrganizationID,
+		"order_id":          id,
+		"reference":         order.Reference,
+		"contact_id":        customer.ContactID,
+		"portal_account_id": customer.AccountID,
+		"amount_total":      order.AmountTotal,
+	})
+	return s.repo.FindOrder(ctx, customer, id)
+}
+
+// expired reports whether a validity date lies before the day of now
+func expired(validity, now time.Time) bool {
+	y, m, d := validity.Date()
+	return now.After(time.Date(y, m, d, 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1))
+}
+
+// ListInvoices returns the customer's posted invoices and credit notes
+func (s *PortalService) ListInvoices(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Invoice, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListInvoices(ctx, customer, limit, offset)
+}
+
+// GetInvoice returns one of the customer's invoices with its lines
+func (s *PortalService) GetInvoice(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Invoice, error) {
+	return s.repo.FindInvoice(ctx, customer, id)
+}
+
+// InvoicePDF renders one of the customer's invoices with the organization's
+// invoice template. It returns the PDF and its file name.
+func (s *PortalService) InvoicePDF(ctx context.Context, customer types.Customer, id uuid.UUID) ([]byte, string, error) {
+	if s.renderer == nil {
+		return nil, "", ErrPDFUnavailable
+	}
+	invoice, err := s.repo.FindInvoice(ctx, customer, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := invoiceData(invoice, customer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// A missing or broken invoice template is the organization's to fix;
+	// the customer only learns the PDF cannot be had right now
+	pdf, err := s.renderer.Render(ctx, customer.OrganizationID, documenttypes.DocumentKindInvoice, data)
+	if err != nil {
+		s.logger.Warn("Failed to render invoice PDF", "invoice_id", invoice.ID, "error", err)
+		return nil, "", ErrPDFUnavailable
+	}
+	name := invoice.Number
+	if name == "" {
+		name = invoice.ID.String()
+	}
+	return pdf, name + ".pdf", nil
+}
+
+// invoiceData is what invoice templates print: the invoice with its lines and
+// the customer it was sent to
+func invoiceData(invoice *types.Invoice, customer types.Customer) (map[string]interface{}, error) {
+	raw, err := json.Marshal(invoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode invoice: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode invoice: %w", err)
+	}
+	data["customer"] = map[string]interface{}{
+		"contact_id": customer.ContactID,
+		"name":       customer.ContactName,
+		"email":      customer.Email,
+	}
+	return data, nil
+}
+
+// ListShipments returns the customer's deliveries
+func (s *PortalService) ListShipments(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Shipment, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListShipments(ctx, customer, limit, offset)
+}
+
+// TrackShipment returns one of the customer's deliveries with its journey.
+// While it is in transit, the position of the vehicle on its delivery route
+// is given, or else the last position the carrier reported.
+func (s *PortalService) TrackShipment(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.ShipmentTracking, error) {
+	shipment, err := s.repo.FindShipment(ctx, customer, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListTrackingEvents(ctx, customer.OrganizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	tracking := &types.ShipmentTracking{Shipment: *shipment, Events: events}
+
+	if shipment.Status == "in_transit" {
+		if shipment.RouteID != nil {
+			position, err := s.repo.LatestRoutePosition(ctx, customer.OrganizationID, *shipment.RouteID)
+			if err != nil {
+				return nil, err
+			}
+			tracking.Position = position
+		}
+		if tracking.Position == nil {
+			tracking.Position = shipment.LastPosition
+		}
+	}
+	return tracking, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceRecords reads the organization's posted invoices regardless of
+// customer
+type InvoiceRecords interface {
+	FindPostedInvoice(ctx context.Context, orgID, id uuid.UUID) (*types.Invoice, *types.Customer, error)
+	ListPostedInvoices(ctx context.Context, orgID uuid.UUID, from, to *time.Time, limit int) ([]types.Invoice, error)
+}
+
+// InvoiceSource is the data source of invoice document templates for the
+// back office, e.g. printing all invoices of a month. Invoices get the same
+// template data as the PDFs customers download from the portal, so one
+// template serves both.
+type InvoiceSource struct {
+	repo InvoiceRecords
+}
+
+func NewInvoiceSource(repo InvoiceRecords) *InvoiceSource {
+	return &InvoiceSource{repo: repo}
+}
+
+// Load returns a posted invoice as template data
+func (s *InvoiceSource) Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error) {
+	invoice, customer, err := s.repo.FindPostedInvoice(ctx, orgID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	return invoiceData(invoice, *customer)
+}
+
+// SelectRecords returns the posted invoices and credit notes dated in the
+// filter's range. Files are named after the invoice number.
+func (s *InvoiceSource) SelectRecords(ctx context.Context, filter documenttypes.RecordFilter) ([]documenttypes.BatchItem, error) {
+	if filter.RouteID != nil {
+		return nil, nil
+	}
+	var to *time.Time
+	if filter.To != nil {
+		// The last day is included
+		end := filter.To.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	invoices, err := s.repo.ListPostedInvoices(ctx, filter.OrganizationID, filter.From, to, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]documenttypes.BatchItem, 0, len(invoices))
+	for _, invoice := range invoices {
+		items = append(items, documenttypes.BatchItem{RecordID: &invoice.ID, Filename: invoice.Number})
+	}
+	return items, nil
+}
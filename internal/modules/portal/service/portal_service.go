@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MagicLinkLifetime is how long a sign-in link can be used
+	MagicLinkLifetime = 15 * time.Minute
+	// SessionLifetime is how long a portal session lasts
+	SessionLifetime = 12 * time.Hour
+	// MinPasswordLength is the shortest password a customer can choose
+	MinPasswordLength = 10
+	// maxPasswordLength is bcrypt's input limit
+	maxPasswordLength = 72
+	// DefaultPageSize is the number of records listed when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize bounds the number of records listed at once
+	MaxPageSize = 200
+	// SignInPath is the portal page magic links open, followed by the token
+	SignInPath = "/sign-in?token="
+	// EventQuoteApproved is published when a customer approves a quote
+	EventQuoteApproved = "portal.quote_approved"
+	// EventTicketCreated is published when a customer raises a support ticket
+	EventTicketCreated = "portal.ticket_created"
+	// EventTicketClosed is published when staff resolve or close a support ticket
+	EventTicketClosed = "portal.ticket_closed"
+	// EventReturnRequested is published when a customer raises a return
+	EventReturnRequested = "portal.return_requested"
+)
+
+var (
+	// ErrInvalid wraps validation failures of portal requests
+	ErrInvalid = errors.New("invalid request")
+	// ErrUnauthorized is returned for a sign-in that fails or a session that
+	// is unknown, expired or revoked. It never tells which.
+	ErrUnauthorized = errors.New("not signed in")
+	// ErrPDFUnavailable is returned for invoice PDFs when no document
+	// renderer is configured
+	ErrPDFUnavailable = errors.New("invoice PDFs are not available")
+)
+
+// AuthService defines the permission check used for staff requests
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer delivers sign-in links to customers
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// DocumentRenderer renders the organization's document template of a kind
+// to PDF
+type DocumentRenderer interface {
+	Render(ctx context.Context, orgID uuid.UUID, kind documenttypes.DocumentKind, data map[string]interface{}) ([]byte, error)
+}
+
+// PortalService serves customers their quotes, orders, invoices and
+// shipments, and takes their quote approvals, support tickets and returns.
+// Customers sign in with magic links or a password to accounts that staff
+// invite for contacts.
+type PortalService struct {
+	repo        repository.PortalRepo
+	authService AuthService
+	eventBus    *events.Bus
+	mailer      Mailer
+	renderer    DocumentRenderer
+	portalURL   string
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewPortalService(repo repository.PortalRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *PortalService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PortalService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails sign-in links. Without a mailer, or without a portal
+// URL, customers can only sign in with a password.
+func (s *PortalService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetPortalURL sets the base URL of the customer portal that sign-in links
+// open
+func (s *PortalService) SetPortalURL(url string) {
+	s.portalURL = strings.TrimRight(url, "/")
+}
+
+// SetRenderer renders invoice PDFs from the organization's invoice template
+func (s *PortalService) SetRenderer(renderer DocumentRenderer) {
+	s.renderer = renderer
+}
+
+func (s *PortalService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish portal event", "event", eventType, "error", err)
+		}
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// ListAccounts returns the organization's portal accounts
+func (s *PortalService) ListAccounts(ctx context.Context, orgID uuid.UUID) ([]types.Account, error) {
+	if err := s.authService.CheckPermission(ctx, "portal:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListAccounts(ctx, orgID)
+}
+
+// InviteAccount creates a portal account for a contact and emails it a
+// sign-in link
+func (s *PortalService) InviteAccount(ctx context.Context, orgID, userID uuid.UUID, req types.AccountRequest) (*types.Account, error) {
+	if err := s.authService.CheckPermission(ctx, "portal:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if req.ContactID == uuid.Nil {
+		return nil, fmt.Errorf("%w: contact_id is required", ErrInvalid)
+	}
+
+	_, contactEmail, err := s.repo.FindContact(ctx, orgID, req.ContactID)
+	if err != nil {
+		return nil, err
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		email = contactEmail
+	}
+	if email == "" {
+		return nil, fmt.Errorf("%w: the contact has no email; give one", ErrInvalid)
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, fmt.Errorf("%w: invalid email %q", ErrInvalid, email)
+	}
+
+	account, err := s.repo.CreateAccount(ctx, types.Account{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ContactID:      req.ContactID,
+		Email:          email,
+		Status:         types.AccountInvited,
+		CreatedAt:      s.now(),
+		CreatedBy:      &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendMagicLink(ctx, *account, "You're invited to our customer portal"); err != nil {
+		s.logger.Warn("Failed to send portal invitation", "account_id", account.ID, "error", err)
+	}
+	return account, nil
+}
+
+// ResendInvite emails an account a new sign-in link. A disabled account is
+// enabled again as invited.
+func (s *PortalService) ResendInvite(ctx context.Context, orgID, id uuid.UUID) (*types.Account, error) {
+	if err := s.authService.CheckPermission(ctx, "portal:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	account, err := s.repo.FindAccount(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountDisabled {
+		if account, err = s.repo.SetAccountStatus(ctx, orgID, id, types.AccountInvited, s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.sendMagicLink(ctx, *account, "You're invited to our customer portal"); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DisableAccount stops an account from signing in and ends its sessions
+func (s *PortalService) DisableAccount(ctx context.Context, orgID, id uuid.UUID) (*types.Account, error) {
+	if err := s.authService.CheckPermission(ctx, "portal:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.SetAccountStatus(ctx, orgID, id, types.AccountDisabled, s.now())
+}
+
+// sendMagicLink stores a new sign-in link for the account and emails it
+func (s *PortalService) sendMagicLink(ctx context.Context, account types.Account, subject string) error {
+	if s.mailer == nil || s.portalURL == "" {
+		return errors.New("sign-in links cannot be emailed: no mailer or portal URL is configured")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if err := s.repo.CreateMagicLink(ctx, types.MagicLink{
+		OrganizationID: account.OrganizationID,
+		AccountID:      account.ID,
+		TokenHash:      hashToken(token),
+		ExpiresAt:      now.Add(MagicLinkLifetime),
+		CreatedAt:      now,
+	}); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nUse this link to sign in to the customer portal. It works once and expires in %d minutes:\n\n%s%s%s\n\nIf you did not ask to sign in, you can ignore this email.\n",
+		account.ContactName, int(MagicLinkLifetime.Minutes()), s.portalURL, SignInPath, token)
+	if err := s.mailer.Send(metering.WithOrganization(ctx, account.OrganizationID), account.Email, subject, body); err != nil {
+		return fmt.Errorf("failed to email sign-in link: %w", err)
+	}
+	return nil
+}
+
+// RequestMagicLink emails a sign-in link to the account with the email in
+// the organization. It reports success whether or not there is such an
+// account, so it cannot be used to find out who is a customer.
+func (s *PortalService) RequestMagicLink(ctx context.Context, req types.MagicLinkRequest) error {
+	if strings.TrimSpace(req.Organization) == "" || strings.TrimSpace(req.Email) == "" {
+		return fmt.Errorf("%w: organization and email are required", ErrInvalid)
+	}
+
+	orgID, err := s.repo.FindOrganizationID(ctx, strings.TrimSpace(req.Organization))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	account, err := s.repo.FindAccountByEmail(ctx, orgID, strings.TrimSpace(req.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if account.Status == types.AccountDisabled {
+		return nil
+	}
+
+	if err := s.sendMagicLink(ctx, *account, "Your sign-in link"); err != nil {
+		s.logger.Warn("Failed to send portal sign-in link", "account_id", account.ID, "error", err)
+	}
+	return nil
+}
+
+// RedeemMagicLink signs in with a sign-in link's token
+func (s *PortalService) RedeemMagicLink(ctx context.Context, req types.TokenRequest) (*types.Session, error) {
+	if req.Token == "" {
+		return nil, ErrUnauthorized
+	}
+	account, err := s.repo.RedeemMagicLink(ctx, hashToken(req.Token), s.now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, *account)
+}
+
+// Login signs in with the password of an active account
+func (s *PortalService) Login(ctx context.Context, req types.LoginRequest) (*types.Session, error) {
+	orgID, err := s.repo.FindOrganizationID(ctx, strings.TrimSpace(req.Organization))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.repo.FindAccountByEmail(ctx, orgID, strings.TrimSpace(req.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != types.AccountActive || account.PasswordHash == "" {
+		return nil, ErrUnauthorized
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrUnauthorized
+	}
+	return s.startSession(ctx, *account)
+}
+
+func (s *PortalService) startSession(ctx context.Context, account types.Account) (*types.Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	expiresAt := now.Add(SessionLifetime)
+	if err := s.repo.CreateSession(ctx, account, hashToken(token), expiresAt, now); err != nil {
+		return nil, err
+	}
+	return &types.Session{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Customer: types.Customer{
+			AccountID:      account.ID,
+			OrganizationID: account.OrganizationID,
+			ContactID:      account.ContactID,
+			ContactName:    account.ContactName,
+			Email:          account.Email,
+			HasPassword:    account.PasswordHash != "",
+		},
+	}, nil
+}
+
+// Authenticate returns the customer signed in with a session token
+func (s *PortalService) Authenticate(ctx context.Context, token string) (*types.Customer, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+	customer, err := s.repo.FindSession(ctx, hashToken(token), s.now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnauthorized
+	}
+	return customer, err
+}
+
+// SignOut ends a session
+func (s *PortalService) SignOut(ctx context.Context, token string) error {
+	return s.repo.RevokeSession(ctx, hashToken(token), s.now())
+}
+
+// SetPassword sets the signed-in customer's password, letting them sign in
+// without a magic link
+func (s *PortalService) SetPassword(ctx context.Context, customer types.Customer, req types.PasswordRequest) error {
+	if len(req.Password) < MinPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalid, MinPasswordLength)
+	}
+	if len(req.Password) > maxPasswordLength {
+		return fmt.Errorf("%w: password must be at most %d bytes", ErrInvalid, maxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.repo.SetPassword(ctx, customer.OrganizationID, customer.AccountID, string(hash), s.now())
+}
@@ -0,0 +1,525 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLink struct {
+	link types.MagicLink
+	used bool
+}
+
+type fakeSession struct {
+	accountID uuid.UUID
+	expiresAt time.Time
+	revoked   bool
+}
+
+type fakePortalRepo struct {
+	orgs     map[string]uuid.UUID
+	accounts []types.Account
+	links    map[string]*fakeLink
+	sessions map[string]*fakeSession
+	orders   []types.Order
+	approved []uuid.UUID
+	returns  []types.Return
+}
+
+func newFakePortalRepo() *fakePortalRepo {
+	return &fakePortalRepo{
+		orgs:     make(map[string]uuid.UUID),
+		links:    make(map[string]*fakeLink),
+		sessions: make(map[string]*fakeSession),
+	}
+}
+
+func (f *fakePortalRepo) FindOrganizationID(ctx context.Context, slug string) (uuid.UUID, error) {
+	if id, ok := f.orgs[slug]; ok {
+		return id, nil
+	}
+	return uuid.Nil, fmt.Errorf("organization %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) FindContact(ctx context.Context, orgID, contactID uuid.UUID) (string, string, error) {
+	return "Acme Ltd", "buyer@acme.test", nil
+}
+
+func (f *fakePortalRepo) ListAccounts(ctx context.Context, orgID uuid.UUID) ([]types.Account, error) {
+	return f.accounts, nil
+}
+
+func (f *fakePortalRepo) FindAccount(ctx context.Context, orgID, id uuid.UUID) (*types.Account, error) {
+	for i := range f.accounts {
+		if f.accounts[i].ID == id {
+			account := f.accounts[i]
+			return &account, nil
+		}
+	}
+	return nil, fmt.Errorf("portal account %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) FindAccountByEmail(ctx context.Context, orgID uuid.UUID, email string) (*types.Account, error) {
+	for i := range f.accounts {
+		if f.accounts[i].OrganizationID == orgID && strings.EqualFold(f.accounts[i].Email, email) {
+			account := f.accounts[i]
+			return &account, nil
+		}
+	}
+	return nil, fmt.Errorf("portal account %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) CreateAccount(ctx context.Context, account types.Account) (*types.Account, error) {
+	account.ContactName = "Acme Ltd"
+	f.accounts = append(f.accounts, account)
+	return &account, nil
+}
+
+func (f *fakePortalRepo) SetAccountStatus(ctx context.Context, orgID, id uuid.UUID, status types.AccountStatus, at time.Time) (*types.Account, error) {
+	for i := range f.accounts {
+		if f.accounts[i].ID == id {
+			f.accounts[i].Status = status
+			return f.FindAccount(ctx, orgID, id)
+		}
+	}
+	return nil, fmt.Errorf("portal account %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) SetPassword(ctx context.Context, orgID, accountID uuid.UUID, hash string, at time.Time) error {
+	for i := range f.accounts {
+		if f.accounts[i].ID == accountID {
+			f.accounts[i].PasswordHash = hash
+			f.accounts[i].HasPassword = true
+			return nil
+		}
+	}
+	return fmt.Errorf("portal account %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) CreateMagicLink(ctx context.Context, link types.MagicLink) error {
+	f.links[link.TokenHash] = &fakeLink{link: link}
+	return nil
+}
+
+func (f *fakePortalRepo) RedeemMagicLink(ctx context.Context, tokenHash string, at time.Time) (*types.Account, error) {
+	link, ok := f.links[tokenHash]
+	if !ok || link.used || !at.Before(link.link.ExpiresAt) {
+		return nil, fmt.Errorf("sign-in link %w", repository.ErrNotFound)
+	}
+	account, err := f.FindAccount(ctx, link.link.OrganizationID, link.link.AccountID)
+	if err != nil || account.Status == types.AccountDisabled {
+		return nil, fmt.Errorf("sign-in link %w", repository.ErrNotFound)
+	}
+	link.used = true
+	return f.SetAccountStatus(ctx, account.OrganizationID, account.ID, types.AccountActive, at)
+}
+
+func (f *fakePortalRepo) CreateSession(ctx context.Context, account types.Account, tokenHash string, expiresAt, at time.Time) error {
+	f.sessions[tokenHash] = &fakeSession{accountID: account.ID, expiresAt: expiresAt}
+	return nil
+}
+
+func (f *fakePortalRepo) FindSession(ctx context.Context, tokenHash string, at time.Time) (*types.Customer, error) {
+	session, ok := f.sessions[tokenHash]
+	if !ok || session.revoked || !at.Before(session.expiresAt) {
+		return nil, fmt.Errorf("session %w", repository.ErrNotFound)
+	}
+	for _, account := range f.accounts {
+		if account.ID == session.accountID && account.Status == types.AccountActive {
+			return &types.Customer{
+				AccountID:      account.ID,
+				OrganizationID: account.OrganizationID,
+				ContactID:      account.ContactID,
+				Email:          account.Email,
+				HasPassword:    account.PasswordHash != "",
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("session %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) RevokeSession(ctx context.Context, tokenHash string, at time.Time) error {
+	if session, ok := f.sessions[tokenHash]; ok {
+		session.revoked = true
+	}
+	return nil
+}
+
+func (f *fakePortalRepo) ListOrders(ctx context.Context, customer types.Customer, quotes bool, limit, offset int) ([]types.Order, error) {
+	return f.orders, nil
+}
+
+func (f *fakePortalRepo) FindOrder(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Order, error) {
+	for i := range f.orders {
+		if f.orders[i].ID == id {
+			order := f.orders[i]
+			return &order, nil
+		}
+	}
+	return nil, fmt.Errorf("order %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) ApproveQuote(ctx context.Context, customer types.Customer, id uuid.UUID, at time.Time) error {
+	for i := range f.orders {
+		if f.orders[i].ID == id {
+			f.orders[i].State = "sale"
+			f.approved = append(f.approved, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("order %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) ListInvoices(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Invoice, error) {
+	return nil, nil
+}
+
+func (f *fakePortalRepo) FindInvoice(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Invoice, error) {
+	return nil, fmt.Errorf("invoice %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) ListShipments(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Shipment, error) {
+	return nil, nil
+}
+
+func (f *fakePortalRepo) FindShipment(ctx context.Context, customer types.Customer, id uuid.UUID) (*types.Shipment, error) {
+	return nil, fmt.Errorf("shipment %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) ListTrackingEvents(ctx context.Context, orgID, shipmentID uuid.UUID) ([]types.TrackingEvent, error) {
+	return []types.TrackingEvent{}, nil
+}
+
+func (f *fakePortalRepo) LatestRoutePosition(ctx context.Context, orgID, routeID uuid.UUID) (*types.Position, error) {
+	return nil, nil
+}
+
+func (f *fakePortalRepo) ListTickets(ctx context.Context, filter types.TicketFilter) ([]types.Ticket, error) {
+	return nil, nil
+}
+
+func (f *fakePortalRepo) FindTicket(ctx context.Context, orgID, id uuid.UUID) (*types.Ticket, error) {
+	return nil, fmt.Errorf("ticket %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) CreateTicket(ctx context.Context, ticket types.Ticket) (*types.Ticket, error) {
+	return &ticket, nil
+}
+
+func (f *fakePortalRepo) SetTicketStatus(ctx context.Context, orgID, id uuid.UUID, status types.TicketStatus, userID *uuid.UUID, at time.Time) (*types.Ticket, error) {
+	return &types.Ticket{ID: id, Status: status}, nil
+}
+
+func (f *fakePortalRepo) ListReturns(ctx context.Context, filter types.ReturnFilter) ([]types.Return, error) {
+	return f.returns, nil
+}
+
+func (f *fakePortalRepo) FindReturn(ctx context.Context, orgID, id uuid.UUID) (*types.Return, error) {
+	for i := range f.returns {
+		if f.returns[i].ID == id {
+			ret := f.returns[i]
+			return &ret, nil
+		}
+	}
+	return nil, fmt.Errorf("return request %w", repository.ErrNotFound)
+}
+
+func (f *fakePortalRepo) CreateReturn(ctx context.Context, customer types.Customer, ret types.Return) (*types.Return, error) {
+	f.returns = append(f.returns, ret)
+	return &ret, nil
+}
+
+func (f *fakePortalRepo) SetReturnStatus(ctx context.Context, orgID, id uuid.UUID, from, to types.ReturnStatus, userID *uuid.UUID, at time.Time) (*types.Return, error) {
+	for i := range f.returns {
+		if f.returns[i].ID == id && f.returns[i].Status == from {
+			f.returns[i].Status = to
+			return f.FindReturn(ctx, orgID, id)
+		}
+	}
+	return nil, fmt.Errorf("return request is no longer %s: %w", from, repository.ErrInvalidState)
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type fakeMailer struct {
+	to     []string
+	bodies []string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+// linkToken picks the token out of the last emailed sign-in link
+func (m *fakeMailer) linkToken(t *testing.T) string {
+	require.NotEmpty(t, m.bodies)
+	body := m.bodies[len(m.bodies)-1]
+	i := strings.Index(body, SignInPath)
+	require.GreaterOrEqual(t, i, 0)
+	return strings.Fields(body[i+len(SignInPath):])[0]
+}
+
+var testNow = time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+
+func newTestService(repo *fakePortalRepo) (*PortalService, *fakeMailer) {
+	mailer := &fakeMailer{}
+	svc := NewPortalService(repo, allowAll{}, nil, nil)
+	svc.SetMailer(mailer)
+	svc.SetPortalURL("https://portal.example.test/")
+	svc.now = func() time.Time { return testNow }
+	return svc, mailer
+}
+
+func TestMagicLinkSignIn(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakePortalRepo()
+	repo.orgs["acme"] = orgID
+	svc, mailer := newTestService(repo)
+
+	account, err := svc.InviteAccount(ctx, orgID, uuid.New(), types.AccountRequest{ContactID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, "buyer@acme.test", account.Email, "email defaults to the contact's")
+	assert.Equal(t, types.AccountInvited, account.Status)
+	require.Len(t, mailer.to, 1)
+	assert.Contains(t, mailer.bodies[0], "https://portal.example.test"+SignInPath)
+	invite := mailer.linkToken(t)
+
+	// Unknown organizations and emails are answered alike, and nothing is sent
+	require.NoError(t, svc.RequestMagicLink(ctx, types.MagicLinkRequest{Organization: "acme", Email: "nobody@acme.test"}))
+	require.NoError(t, svc.RequestMagicLink(ctx, types.MagicLinkRequest{Organization: "other", Email: "buyer@acme.test"}))
+	assert.Len(t, mailer.to, 1)
+
+	session, err := svc.RedeemMagicLink(ctx, types.TokenRequest{Token: invite})
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, session.Customer.AccountID)
+	assert.Equal(t, testNow.Add(SessionLifetime), session.ExpiresAt)
+
+	// Links work once
+	_, err = svc.RedeemMagicLink(ctx, types.TokenRequest{Token: invite})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	customer, err := svc.Authenticate(ctx, session.Token)
+	require.NoError(t, err)
+	assert.Equal(t, account.ContactID, customer.ContactID)
+
+	// Links expire
+	require.NoError(t, svc.RequestMagicLink(ctx, types.MagicLinkRequest{Organization: "acme", Email: "BUYER@acme.test"}))
+	require.Len(t, mailer.to, 2)
+	svc.now = func() time.Time { return testNow.Add(MagicLinkLifetime) }
+	_, err = svc.RedeemMagicLink(ctx, types.TokenRequest{Token: mailer.linkToken(t)})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	require.NoError(t, svc.SignOut(ctx, session.Token))
+	_, err = svc.Authenticate(ctx, session.Token)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestDisabledAccountCannotSignIn(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakePortalRepo()
+	repo.orgs["acme"] = orgID
+	svc, mailer := newTestService(repo)
+
+	account, err := svc.InviteAccount(ctx, orgID, uuid.New(), types.AccountRequest{ContactID: uuid.New(), Email: "ap@acme.test"})
+	require.NoError(t, err)
+	invite := mailer.linkToken(t)
+	_, err = svc.DisableAccount(ctx, orgID, account.ID)
+	require.NoError(t, err)
+
+	_, err = svc.RedeemMagicLink(ctx, types.TokenRequest{Token: invite})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	require.NoError(t, svc.RequestMagicLink(ctx, types.MagicLinkRequest{Organization: "acme", Email: "ap@acme.test"}))
+	assert.Len(t, mailer.to, 1, "disabled accounts get no sign-in links")
+
+	// Inviting again enables the account
+	account, err = svc.ResendInvite(ctx, orgID, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.AccountInvited, account.Status)
+	_, err = svc.RedeemMagicLink(ctx, types.TokenRequest{Token: mailer.linkToken(t)})
+	assert.NoError(t, err)
+}
+
+func TestPasswordLogin(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	repo := newFakePortalRepo()
+	repo.orgs["acme"] = orgID
+	svc, mailer := newTestService(repo)
+
+	_, err := svc.InviteAccount(ctx, orgID, uuid.New(), types.AccountRequest{ContactID: uuid.New()})
+	require.NoError(t, err)
+	session, err := svc.RedeemMagicLink(ctx, types.TokenRequest{Token: mailer.linkToken(t)})
+	require.NoError(t, err)
+
+	login := types.LoginRequest{Organization: "acme", Email: "buyer@acme.test", Password: "correct horse battery"}
+	_, err = svc.Login(ctx, login)
+	assert.ErrorIs(t, err, ErrUnauthorized, "no password is set yet")
+
+	err = svc.SetPassword(ctx, session.Customer, types.PasswordRequest{Password: "short"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	require.NoError(t, svc.SetPassword(ctx, session.Customer, types.PasswordRequest{Password: login.Password}))
+
+	_, err = svc.Login(ctx, types.LoginRequest{Organization: "acme", Email: login.Email, Password: "wrong horse battery"})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = svc.Login(ctx, types.LoginRequest{Organization: "other", Email: login.Email, Password: login.Password})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	second, err := svc.Login(ctx, login)
+	require.NoError(t, err)
+	assert.True(t, second.Customer.HasPassword)
+	assert.NotEqual(t, session.Token, second.Token)
+}
+
+func TestApproveQuote(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakePortalRepo()
+	svc, _ := newTestService(repo)
+	customer := types.Customer{AccountID: uuid.New(), OrganizationID: uuid.New(), ContactID: uuid.New()}
+
+	today := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+	valid := types.Order{ID: uuid.New(), Reference: "S00012", State: "sent", ValidityDate: &today}
+	expiredQuote := types.Order{ID: uuid.New(), Reference: "S00011", State: "sent", ValidityDate: &yesterday}
+	confirmed := types.Order{ID: uuid.New(), Reference: "S00010", State: "sale"}
+	repo.orders = []types.Order{valid, expiredQuote, confirmed}
+
+	_, err := svc.ApproveQuote(ctx, customer, expiredQuote.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+	_, err = svc.ApproveQuote(ctx, customer, confirmed.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+	_, err = svc.ApproveQuote(ctx, customer, uuid.New())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	order, err := svc.ApproveQuote(ctx, customer, valid.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sale", order.State, "a quote is valid through its validity date")
+	assert.Equal(t, []uuid.UUID{valid.ID}, repo.approved)
+}
+
+func TestCreateReturnValidation(t *testing.T) {
+	ctx := context.Background()
+	customer := types.Customer{AccountID: uuid.New(), OrganizationID: uuid.New(), ContactID: uuid.New()}
+	lineID := uuid.New()
+	valid := func() types.ReturnRequest {
+		return types.ReturnRequest{
+			OrderID: uuid.New(),
+			Reason:  "Arrived damaged",
+			Lines:   []types.ReturnLine{{OrderLineID: lineID, Quantity: 2}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*types.ReturnRequest)
+	}{
+		{"no order", func(r *types.ReturnRequest) { r.OrderID = uuid.Nil }},
+		{"no reason", func(r *types.ReturnRequest) { r.Reason = "  " }},
+		{"no lines", func(r *types.ReturnRequest) { r.Lines = nil }},
+		{"no order line", func(r *types.ReturnRequest) { r.Lines[0].OrderLineID = uuid.Nil }},
+		{"zero quantity", func(r *types.ReturnRequest) { r.Lines[0].Quantity = 0 }},
+		{"line twice", func(r *types.ReturnRequest) {
+			r.Lines = append(r.Lines, types.ReturnLine{OrderLineID: lineID, Quantity: 1})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(newFakePortalRepo())
+			req := valid()
+			tt.modify(&req)
+			_, err := svc.CreateReturn(ctx, customer, req)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+
+	svc, _ := newTestService(newFakePortalRepo())
+	ret, err := svc.CreateReturn(ctx, customer, valid())
+	require.NoError(t, err)
+	assert.Equal(t, types.ReturnRequested, ret.Status)
+	assert.Equal(t, customer.ContactID, ret.ContactID)
+	assert.Equal(t, &customer.AccountID, ret.PortalAccountID)
+}
+
+func TestSetReturnStatus(t *testing.T) {
+	ctx := context.Background()
+	orgID, userID := uuid.New(), uuid.New()
+	repo := newFakePortalRepo()
+	svc, _ := newTestService(repo)
+	ret := types.Return{ID: uuid.New(), OrganizationID: orgID, Status: types.ReturnRequested}
+	repo.returns = []types.Return{ret}
+
+	_, err := svc.SetReturnStatus(ctx, orgID, userID, ret.ID, types.ReturnStatusRequest{Status: types.ReturnRefunded})
+	assert.ErrorIs(t, err, repository.ErrInvalidState, "a request is approved before it is refunded")
+	_, err = svc.SetReturnStatus(ctx, orgID, userID, ret.ID, types.ReturnStatusRequest{Status: "lost"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	for _, status := range []types.ReturnStatus{types.ReturnApproved, types.ReturnReceived, types.ReturnRefunded} {
+		updated, err := svc.SetReturnStatus(ctx, orgID, userID, ret.ID, types.ReturnStatusRequest{Status: status})
+		require.NoError(t, err)
+		assert.Equal(t, status, updated.Status)
+	}
+
+	_, err = svc.SetReturnStatus(ctx, orgID, userID, ret.ID, types.ReturnStatusRequest{Status: types.ReturnRejected})
+	assert.ErrorIs(t, err, repository.ErrInvalidState, "refunded returns are final")
+}
+
+type fakeInvoiceRecords struct {
+	invoices []types.Invoice
+	from, to *time.Time
+}
+
+func (f *fakeInvoiceRecords) FindPostedInvoice(ctx context.Context, orgID, id uuid.UUID) (*types.Invoice, *types.Customer, error) {
+	for _, invoice := range f.invoices {
+		if invoice.ID == id {
+			return &invoice, &types.Customer{OrganizationID: orgID, ContactName: "Globex"}, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("invoice %w", repository.ErrNotFound)
+}
+
+func (f *fakeInvoiceRecords) ListPostedInvoices(ctx context.Context, orgID uuid.UUID, from, to *time.Time, limit int) ([]types.Invoice, error) {
+	f.from, f.to = from, to
+	return f.invoices, nil
+}
+
+func TestInvoiceSource(t *testing.T) {
+	repo := &fakeInvoiceRecords{invoices: []types.Invoice{
+		{ID: uuid.New(), Number: "INV/2025/0001"},
+		{ID: uuid.New(), Number: "RINV/2025/0001", MoveType: "out_refund"},
+	}}
+	source := NewInvoiceSource(repo)
+	ctx := context.Background()
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	items, err := source.SelectRecords(ctx, documenttypes.RecordFilter{OrganizationID: uuid.New(), From: &from, To: &to, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "INV/2025/0001", items[0].Filename)
+	assert.Equal(t, repo.invoices[1].ID, *items[1].RecordID)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), *repo.to, "the last day is included")
+
+	data, err := source.Load(ctx, uuid.New(), repo.invoices[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "INV/2025/0001", data["number"])
+	assert.Equal(t, "Globex", data["customer"].(map[string]interface{})["name"], "invoices print the same data as in the portal")
+
+	_, err = source.Load(ctx, uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/portal/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxSubjectLength bounds a ticket subject, as the column does
+	maxSubjectLength = 255
+	// maxReturnLines bounds the lines of a return request
+	maxReturnLines = 200
+)
+
+// ListTickets returns the customer's open tickets, or all their tickets
+func (s *PortalService) ListTickets(ctx context.Context, customer types.Customer, all bool, limit, offset int) ([]types.Ticket, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListTickets(ctx, types.TicketFilter{
+		OrganizationID: customer.OrganizationID,
+		ContactID:      &customer.ContactID,
+		OpenOnly:       !all,
+		Limit:          limit,
+		Offset:         offset,
+	})
+}
+
+// CreateTicket raises a support ticket for the customer
+func (s *PortalService) CreateTicket(ctx context.Context, customer types.Customer, req types.TicketRequest) (*types.Ticket, error) {
+	subject := strings.TrimSpace(req.Subject)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalid)
+	}
+	if len(subject) > maxSubjectLength {
+		return nil, fmt.Errorf("%w: subject must be at most %d characters", ErrInvalid, maxSubjectLength)
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return nil, fmt.Errorf("%w: description is required", ErrInvalid)
+	}
+	if req.Priority == "" {
+		req.Priority = types.TicketPriorityNormal
+	}
+	if !req.Priority.IsValid() {
+		return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalid, req.Priority)
+	}
+
+	ticket, err := s.repo.CreateTicket(ctx, types.Ticket{
+		ID:              uuid.New(),
+		OrganizationID:  customer.OrganizationID,
+		ContactID:       customer.ContactID,
+		PortalAccountID: &customer.AccountID,
+		Subject:         subject,
+		Description:     strings.TrimSpace(req.Description),
+		Status:          types.TicketOpen,
+		Priority:        req.Priority,
+		CreatedAt:       s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventTicketCreated, map[string]interface{}{
+		"organization_id": ticket.OrganizationID,
+		"ticket_id":       ticket.ID,
+		"contact_id":      ticket.ContactID,
+		"subject":         ticket.Subject,
+		"priority":        ticket.Priority,
+	})
+	return ticket, nil
+}
+
+// ListReturns returns the customer's return requests
+func (s *PortalService) ListReturns(ctx context.Context, customer types.Customer, limit, offset int) ([]types.Return, error) {
+	limit, offset = page(limit, offset)
+	return s.repo.ListReturns(ctx, types.ReturnFilter{
+		OrganizationID: customer.OrganizationID,
+		ContactID:      &customer.ContactID,
+		Limit:          limit,
+		Offset:         offset,
+	})
+}
+
+// CreateReturn raises a return of delivered goods of one of the customer's
+// confirmed orders. No line can return more than was delivered less what
+// other return requests already return.
+func (s *PortalService) CreateReturn(ctx context.Context, customer types.Customer, req types.ReturnRequest) (*types.Return, error) {
+	if req.OrderID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id is required", ErrInvalid)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalid)
+	}
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalid)
+	}
+	if len(req.Lines) > maxReturnLines {
+		return nil, fmt.Errorf("%w: at most %d lines can be returned at once", ErrInvalid, maxReturnLines)
+	}
+	seen := make(map[uuid.UUID]bool, len(req.Lines))
+	lines := make([]types.ReturnLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		if line.OrderLineID == uuid.Nil {
+			return nil, fmt.Errorf("%w: order_line_id is required", ErrInvalid)
+		}
+		if seen[line.OrderLineID] {
+			return nil, fmt.Errorf("%w: order line %s is listed twice", ErrInvalid, line.OrderLineID)
+		}
+		seen[line.OrderLineID] = true
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity of order line %s must be positive", ErrInvalid, line.OrderLineID)
+		}
+		lines = append(lines, types.ReturnLine{OrderLineID: line.OrderLineID, Quantity: line.Quantity})
+	}
+
+	ret, err := s.repo.CreateReturn(ctx, customer, types.Return{
+		ID:              uuid.New(),
+		OrganizationID:  customer.OrganizationID,
+		ContactID:       customer.ContactID,
+		OrderID:         req.OrderID,
+		PortalAccountID: &customer.AccountID,
+		Reason:          reason,
+		Status:          types.ReturnRequested,
+		Lines:           lines,
+		CreatedAt:       s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventReturnRequested, map[string]interface{}{
+		"organization_id": ret.OrganizationID,
+		"return_id":       ret.ID,
+		"order_id":        ret.OrderID,
+		"contact_id":      ret.ContactID,
+	})
+	return ret, nil
+}
+
+// ListSupportTickets returns the organization's support tickets for staff
+func (s *PortalService) ListSupportTickets(ctx context.Context, filter types.TicketFilter) ([]types.Ticket, error) {
+	if err := s.authService.CheckPermission(ctx, "support_tickets:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListTickets(ctx, filter)
+}
+
+// SetTicketStatus moves a support ticket to a status. Tickets can be
+// reopened from any status.
+func (s *PortalService) SetTicketStatus(ctx context.Context, orgID, userID, id uuid.UUID, req types.TicketStatusRequest) (*types.Ticket, error) {
+	if err := s.authService.CheckPermission(ctx, "support_tickets:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !req.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, req.Status)
+	}
+	ticket, err := s.repo.SetTicketStatus(ctx, orgID, id, req.Status, &userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status == types.TicketResolved || ticket.Status == types.TicketClosed {
+		s.publishEvent(ctx, EventTicketClosed, map[string]interface{}{
+			"organization_id": ticket.OrganizationID,
+			"ticket_id":       ticket.ID,
+			"contact_id":      ticket.ContactID,
+			"status":          ticket.Status,
+		})
+	}
+	return ticket, nil
+}
+
+// ListReturnRequests returns the organization's return requests for staff
+func (s *PortalService) ListReturnRequests(ctx context.Context, filter types.ReturnFilter) ([]types.Return, error) {
+	if err := s.authService.CheckPermission(ctx, "return_requests:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListReturns(ctx, filter)
+}
+
+// SetReturnStatus moves a return request along its lifecycle: requests are
+// approved or rejected, approved returns received and received returns
+// refunded
+func (s *PortalService) SetReturnStatus(ctx context.Context, orgID, userID, id uuid.UUID, req types.ReturnStatusRequest) (*types.Return, error) {
+	if err := s.authService.CheckPermission(ctx, "return_requests:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !req.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, req.Status)
+	}
+
+	ret, err := s.repo.FindReturn(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ret.Status.CanBecome(req.Status) {
+		return nil, fmt.Errorf("a %s return cannot become %s: %w", ret.Status, req.Status, repository.ErrInvalidState)
+	}
+	return s.repo.SetReturnStatus(ctx, orgID, id, ret.Status, req.Status, &userID, s.now())
+}
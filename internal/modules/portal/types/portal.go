@@ -0,0 +1,352 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountStatus is whether a portal account can sign in
+type AccountStatus string
+
+const (
+	// AccountInvited accounts have not signed in yet
+	AccountInvited  AccountStatus = "invited"
+	AccountActive   AccountStatus = "active"
+	AccountDisabled AccountStatus = "disabled"
+)
+
+// Account is a customer's sign-in to the portal, tied to a contact
+type Account struct {
+	ID             uuid.UUID     `json:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id"`
+	ContactID      uuid.UUID     `json:"contact_id"`
+	ContactName    string        `json:"contact_name"`
+	Email          string        `json:"email"`
+	Status         AccountStatus `json:"status"`
+	HasPassword    bool          `json:"has_password"`
+	LastLoginAt    *time.Time    `json:"last_login_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	CreatedBy      *uuid.UUID    `json:"created_by,omitempty"`
+	PasswordHash   string        `json:"-"`
+}
+
+// AccountRequest invites a contact to the portal. Email defaults to the
+// contact's email.
+type AccountRequest struct {
+	ContactID uuid.UUID `json:"contact_id"`
+	Email     string    `json:"email"`
+}
+
+// Customer is the signed-in portal account. Its documents are those of its
+// contact and the contact's child contacts.
+type Customer struct {
+	AccountID      uuid.UUID `json:"account_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	ContactID      uuid.UUID `json:"contact_id"`
+	ContactName    string    `json:"contact_name"`
+	Email          string    `json:"email"`
+	HasPassword    bool      `json:"has_password"`
+}
+
+// MagicLink is a single-use sign-in link sent to an account's email
+type MagicLink struct {
+	OrganizationID uuid.UUID
+	AccountID      uuid.UUID
+	TokenHash      string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+// Session is a signed-in portal session. The token is only returned when
+// the session starts.
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Customer  Customer  `json:"customer"`
+}
+
+// MagicLinkRequest asks for a sign-in link. Organization is the
+// organization's slug.
+type MagicLinkRequest struct {
+	Organization string `json:"organization"`
+	Email        string `json:"email"`
+}
+
+// TokenRequest redeems a magic link's token for a session
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+// LoginRequest signs in with a password. Organization is the organization's
+// slug.
+type LoginRequest struct {
+	Organization string `json:"organization"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+}
+
+// PasswordRequest sets the signed-in account's password
+type PasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// Order is a quote or a confirmed sales order as the customer sees it
+type Order struct {
+	ID               uuid.UUID   `json:"id"`
+	Reference        string      `json:"reference"`
+	CustomerRef      string      `json:"customer_ref,omitempty"`
+	State            string      `json:"state"`
+	OrderDate        time.Time   `json:"order_date"`
+	ValidityDate     *time.Time  `json:"validity_date,omitempty"`
+	ConfirmationDate *time.Time  `json:"confirmation_date,omitempty"`
+	InvoiceStatus    string      `json:"invoice_status"`
+	DeliveryStatus   string      `json:"delivery_status"`
+	AmountUntaxed    float64     `json:"amount_untaxed"`
+	AmountTax        float64     `json:"amount_tax"`
+	AmountTotal      float64     `json:"amount_total"`
+	Note             string      `json:"note,omitempty"`
+	Lines            []OrderLine `json:"lines,omitempty"`
+}
+
+// OrderLine is a product line of a quote or order
+type OrderLine struct {
+	ID                uuid.UUID  `json:"id"`
+	Name              string     `json:"name"`
+	ProductID         *uuid.UUID `json:"product_id,omitempty"`
+	Quantity          float64    `json:"quantity"`
+	QuantityDelivered float64    `json:"quantity_delivered"`
+	PriceUnit         float64    `json:"price_unit"`
+	Discount          float64    `json:"discount"`
+	PriceSubtotal     float64    `json:"price_subtotal"`
+	PriceTotal        float64    `json:"price_total"`
+}
+
+// Invoice is a posted customer invoice or credit note
+type Invoice struct {
+	ID             uuid.UUID     `json:"id"`
+	Number         string        `json:"number"`
+	MoveType       string        `json:"move_type"`
+	InvoiceDate    *time.Time    `json:"invoice_date,omitempty"`
+	DueDate        *time.Time    `json:"due_date,omitempty"`
+	PaymentState   string        `json:"payment_state"`
+	Origin         string        `json:"origin,omitempty"`
+	AmountUntaxed  float64       `json:"amount_untaxed"`
+	AmountTax      float64       `json:"amount_tax"`
+	AmountTotal    float64       `json:"amount_total"`
+	AmountResidual float64       `json:"amount_residual"`
+	Lines          []InvoiceLine `json:"lines,omitempty"`
+}
+
+// InvoiceLine is a line of an invoice
+type InvoiceLine struct {
+	Name          string  `json:"name"`
+	Quantity      float64 `json:"quantity"`
+	PriceUnit     float64 `json:"price_unit"`
+	Discount      float64 `json:"discount"`
+	PriceSubtotal float64 `json:"price_subtotal"`
+	PriceTotal    float64 `json:"price_total"`
+}
+
+// Shipment is an outbound delivery to the customer
+type Shipment struct {
+	ID                 uuid.UUID  `json:"id"`
+	Reference          string     `json:"reference"`
+	Origin             string     `json:"origin,omitempty"`
+	TrackingNumber     string     `json:"tracking_number,omitempty"`
+	CarrierName        string     `json:"carrier_name,omitempty"`
+	Status             string     `json:"status"`
+	EstimatedArrivalAt *time.Time `json:"estimated_arrival_at,omitempty"`
+	DepartedAt         *time.Time `json:"departed_at,omitempty"`
+	ArrivedAt          *time.Time `json:"arrived_at,omitempty"`
+	LastEventAt        *time.Time `json:"last_event_at,omitempty"`
+	// RouteID and LastPosition locate the shipment for live tracking
+	RouteID      *uuid.UUID `json:"-"`
+	LastPosition *Position  `json:"-"`
+}
+
+// Position is where a shipment was at a time
+type Position struct {
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// TrackingEvent is a step of a shipment's journey
+type TrackingEvent struct {
+	EventType string    `json:"event_type"`
+	Status    string    `json:"status,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	EventTime time.Time `json:"event_time"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+}
+
+// ShipmentTracking is a shipment with its journey. Position is the live
+// position of the delivery vehicle and is only given while the shipment is
+// in transit.
+type ShipmentTracking struct {
+	Shipment
+	Position *Position       `json:"position,omitempty"`
+	Events   []TrackingEvent `json:"events"`
+}
+
+// TicketStatus is where a support ticket is in its lifecycle
+type TicketStatus string
+
+const (
+	TicketOpen     TicketStatus = "open"
+	TicketPending  TicketStatus = "pending"
+	TicketResolved TicketStatus = "resolved"
+	TicketClosed   TicketStatus = "closed"
+)
+
+// IsValid reports whether the status is known
+func (s TicketStatus) IsValid() bool {
+	switch s {
+	case TicketOpen, TicketPending, TicketResolved, TicketClosed:
+		return true
+	}
+	return false
+}
+
+// IsOpen reports whether the ticket still awaits an answer or a fix
+func (s TicketStatus) IsOpen() bool {
+	return s == TicketOpen || s == TicketPending
+}
+
+// TicketPriority is how urgent a support ticket is
+type TicketPriority string
+
+const (
+	TicketPriorityLow    TicketPriority = "low"
+	TicketPriorityNormal TicketPriority = "normal"
+	TicketPriorityHigh   TicketPriority = "high"
+	TicketPriorityUrgent TicketPriority = "urgent"
+)
+
+// IsValid reports whether the priority is known
+func (p TicketPriority) IsValid() bool {
+	switch p {
+	case TicketPriorityLow, TicketPriorityNormal, TicketPriorityHigh, TicketPriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// Ticket is a support ticket raised by a customer
+type Ticket struct {
+	ID              uuid.UUID      `json:"id"`
+	OrganizationID  uuid.UUID      `json:"organization_id"`
+	ContactID       uuid.UUID      `json:"contact_id"`
+	PortalAccountID *uuid.UUID     `json:"portal_account_id,omitempty"`
+	Subject         string         `json:"subject"`
+	Description     string         `json:"description"`
+	Status          TicketStatus   `json:"status"`
+	Priority        TicketPriority `json:"priority"`
+	ResolvedAt      *time.Time     `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// TicketRequest raises a support ticket. Priority defaults to normal.
+type TicketRequest struct {
+	Subject     string         `json:"subject"`
+	Description string         `json:"description"`
+	Priority    TicketPriority `json:"priority"`
+}
+
+// TicketFilter selects support tickets. OpenOnly keeps open and pending
+// tickets.
+type TicketFilter struct {
+	OrganizationID uuid.UUID
+	ContactID      *uuid.UUID
+	Status         TicketStatus
+	OpenOnly       bool
+	Limit          int
+	Offset         int
+}
+
+// ReturnStatus is where a return request is in its lifecycle
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+// IsValid reports whether the status is known
+func (s ReturnStatus) IsValid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnReceived, ReturnRefunded:
+		return true
+	}
+	return false
+}
+
+// CanBecome reports whether a return in this status can move to next.
+// Requests are approved or rejected, approved returns are received and
+// received returns refunded.
+func (s ReturnStatus) CanBecome(next ReturnStatus) bool {
+	switch s {
+	case ReturnRequested:
+		return next == ReturnApproved || next == ReturnRejected
+	case ReturnApproved:
+		return next == ReturnReceived || next == ReturnRejected
+	case ReturnReceived:
+		return next == ReturnRefunded
+	}
+	return false
+}
+
+// Return is a customer's request to return goods of an order
+type Return struct {
+	ID              uuid.UUID    `json:"id"`
+	OrganizationID  uuid.UUID    `json:"organization_id"`
+	ContactID       uuid.UUID    `json:"contact_id"`
+	OrderID         uuid.UUID    `json:"order_id"`
+	OrderReference  string       `json:"order_reference"`
+	PortalAccountID *uuid.UUID   `json:"portal_account_id,omitempty"`
+	Reason          string       `json:"reason"`
+	Status          ReturnStatus `json:"status"`
+	Lines           []ReturnLine `json:"lines"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// ReturnLine is a quantity of an order line to return
+type ReturnLine struct {
+	OrderLineID uuid.UUID `json:"order_line_id"`
+	Name        string    `json:"name,omitempty"`
+	Quantity    float64   `json:"quantity"`
+}
+
+// ReturnRequest raises a return of delivered goods of a confirmed order
+type ReturnRequest struct {
+	OrderID uuid.UUID    `json:"order_id"`
+	Reason  string       `json:"reason"`
+	Lines   []ReturnLine `json:"lines"`
+}
+
+// ReturnFilter selects return requests
+type ReturnFilter struct {
+	OrganizationID uuid.UUID
+	ContactID      *uuid.UUID
+	Status         ReturnStatus
+	Limit          int
+	Offset         int
+}
+
+// TicketStatusRequest moves a support ticket to a status
+type TicketStatusRequest struct {
+	Status TicketStatus `json:"status"`
+}
+
+// ReturnStatusRequest moves a return request to a status
+type ReturnStatusRequest struct {
+	Status ReturnStatus `json:"status"`
+}
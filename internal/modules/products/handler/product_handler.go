@@ -7,6 +7,7 @@ import (
 
 	"github.com/KevTiv/alieze-erp/internal/modules/products/types"
 	"github.com/KevTiv/alieze-erp/internal/modules/products/service"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -89,6 +90,13 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request, _
 		Offset: 0,
 	}
 
+	matchMode, err := database.ParseMatchMode(r.URL.Query().Get("match_mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.MatchMode = matchMode
+
 	if name != "" {
 		filters.Name = &name
 	}
@@ -38,6 +38,9 @@ func (m *ProductsModule) Init(ctx context.Context, deps registry.Dependencies) e
 
 	// Create services
 	productService := service.NewProductService(productRepo, nil)
+	if deps.EventBus != nil {
+		productService.SetEventPublisher(deps.EventBus)
+	}
 
 	// Create handlers
 	m.productHandler = handler.NewProductHandler(productService)
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/KevTiv/alieze-erp/internal/modules/products/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 
 	"github.com/google/uuid"
 )
@@ -148,20 +149,20 @@ func (r *ProductRepository) FindAll(ctx context.Context, filter types.ProductFil
 	argIndex++
 
 	if filter.Name != nil && *filter.Name != "" {
-		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Name+"%")
+		conditions = append(conditions, database.ILike("name", argIndex))
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.DefaultCode != nil && *filter.DefaultCode != "" {
-		conditions = append(conditions, fmt.Sprintf("default_code ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.DefaultCode+"%")
+		conditions = append(conditions, database.ILike("default_code", argIndex))
+		args = append(args, database.LikePattern(*filter.DefaultCode, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Barcode != nil && *filter.Barcode != "" {
-		conditions = append(conditions, fmt.Sprintf("barcode ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Barcode+"%")
+		conditions = append(conditions, database.ILike("barcode", argIndex))
+		args = append(args, database.LikePattern(*filter.Barcode, filter.MatchMode))
 		argIndex++
 	}
 
@@ -343,20 +344,20 @@ func (r *ProductRepository) Count(ctx context.Context, filter types.ProductFilte
 	argIndex++
 
 	if filter.Name != nil && *filter.Name != "" {
-		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Name+"%")
+		conditions = append(conditions, database.ILike("name", argIndex))
+		args = append(args, database.LikePattern(*filter.Name, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.DefaultCode != nil && *filter.DefaultCode != "" {
-		conditions = append(conditions, fmt.Sprintf("default_code ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.DefaultCode+"%")
+		conditions = append(conditions, database.ILike("default_code", argIndex))
+		args = append(args, database.LikePattern(*filter.DefaultCode, filter.MatchMode))
 		argIndex++
 	}
 
 	if filter.Barcode != nil && *filter.Barcode != "" {
-		conditions = append(conditions, fmt.Sprintf("barcode ILIKE $%d", argIndex))
-		args = append(args, "%"+*filter.Barcode+"%")
+		conditions = append(conditions, database.ILike("barcode", argIndex))
+		args = append(args, database.LikePattern(*filter.Barcode, filter.MatchMode))
 		argIndex++
 	}
 
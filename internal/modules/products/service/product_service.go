@@ -20,10 +20,23 @@ type AuthService interface {
 	CheckPermission(ctx context.Context, permission string) error
 }
 
+// Events published on product changes so other modules can react to them
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+)
+
+// EventPublisher publishes product change events
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, payload interface{}) error
+}
+
 // ProductService handles product business logic
 type ProductService struct {
 	repo        repository.ProductRepo
 	authService AuthService
+	events      EventPublisher
 	logger      *log.Logger
 }
 
@@ -35,6 +48,22 @@ func NewProductService(repo repository.ProductRepo, authService AuthService) *Pr
 	}
 }
 
+// SetEventPublisher enables product change events
+func (s *ProductService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// publishEvent publishes a product change event if events are enabled
+func (s *ProductService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, eventType, payload); err != nil {
+		// Log error but don't fail the operation
+		s.logger.Printf("Failed to publish event %s: %v", eventType, err)
+	}
+}
+
 func (s *ProductService) CreateProduct(ctx context.Context, product types.Product) (*types.Product, error) {
 	// Validate required fields
 	if product.Name == "" {
@@ -74,6 +103,7 @@ func (s *ProductService) CreateProduct(ctx context.Context, product types.Produc
 	}
 
 	s.logger.Printf("Created product %s for organization %s", created.ID, created.OrganizationID)
+	s.publishEvent(ctx, EventProductCreated, created)
 
 	return created, nil
 }
@@ -181,6 +211,7 @@ func (s *ProductService) UpdateProduct(ctx context.Context, product types.Produc
 	}
 
 	s.logger.Printf("Updated product %s for organization %s", updated.ID, updated.OrganizationID)
+	s.publishEvent(ctx, EventProductUpdated, updated)
 
 	return updated, nil
 }
@@ -217,6 +248,10 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 	}
 
 	s.logger.Printf("Deleted product %s for organization %s", id, orgID)
+	s.publishEvent(ctx, EventProductDeleted, map[string]interface{}{
+		"id":              id,
+		"organization_id": orgID,
+	})
 
 	return nil
 }
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
 	"github.com/google/uuid"
 )
 
@@ -31,6 +33,7 @@ type ProductFilter struct {
 	ProductType    *string
 	CategoryID     *uuid.UUID
 	Active         *bool
+	MatchMode      database.MatchMode
 	Limit          int
 	Offset         int
 }
@@ -0,0 +1,876 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxApplicationSize bounds the body of careers page applications
+const maxApplicationSize = 64 << 10
+
+// RecruitmentHandler handles recruitment stages, job postings, candidates,
+// interviews and the public careers page endpoints
+type RecruitmentHandler struct {
+	service *service.RecruitmentService
+}
+
+func NewRecruitmentHandler(service *service.RecruitmentService) *RecruitmentHandler {
+	return &RecruitmentHandler{service: service}
+}
+
+func (h *RecruitmentHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/recruitment-stages", h.ListStages)
+	router.POST("/api/recruitment-stages", h.CreateStage)
+	router.PUT("/api/recruitment-stages/:id", h.UpdateStage)
+	router.DELETE("/api/recruitment-stages/:id", h.DeleteStage)
+
+	router.GET("/api/job-postings", h.ListPostings)
+	router.POST("/api/job-postings", h.CreatePosting)
+	router.GET("/api/job-postings/:id", h.GetPosting)
+	router.PUT("/api/job-postings/:id", h.UpdatePosting)
+	router.POST("/api/job-postings/:id/publish", h.PublishPosting)
+	router.POST("/api/job-postings/:id/close", h.ClosePosting)
+
+	router.GET("/api/candidates", h.ListCandidates)
+	router.POST("/api/candidates", h.CreateCandidate)
+	router.GET("/api/candidates/:id", h.GetCandidate)
+	router.PUT("/api/candidates/:id", h.UpdateCandidate)
+	router.POST("/api/candidates/:id/move", h.MoveCandidate)
+	router.POST("/api/candidates/:id/refuse", h.RefuseCandidate)
+	router.GET("/api/candidates/:id/stage-history", h.StageHistory)
+	router.POST("/api/candidates/:id/documents", h.AddDocument)
+	router.DELETE("/api/candidates/:id/documents/:documentId", h.DeleteDocument)
+	router.GET("/api/candidates/:id/activities", h.ListActivities)
+	router.POST("/api/candidates/:id/activities", h.CreateActivity)
+	router.POST("/api/candidates/:id/interviews", h.ScheduleInterview)
+
+	router.GET("/api/interviews", h.ListInterviews)
+	router.PUT("/api/interviews/:id", h.RescheduleInterview)
+	router.POST("/api/interviews/:id/complete", h.CompleteInterview)
+	router.POST("/api/interviews/:id/cancel", h.CancelInterview)
+	router.GET("/api/interview-slots", h.FreeSlots)
+
+	router.GET("/public/v1/careers/:slug", h.CareersPostings)
+	router.GET("/public/v1/careers/:slug/postings/:posting", h.CareersPosting)
+	router.POST("/public/v1/careers/:slug/postings/:posting/apply", h.Apply)
+}
+
+// ListStages handles GET /api/recruitment-stages. job_posting_id returns
+// the pipeline of that posting instead of the default one.
+func (h *RecruitmentHandler) ListStages(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var postingID *uuid.UUID
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"job_posting_id": &postingID}) {
+		return
+	}
+
+	stages, err := h.service.ListStages(r.Context(), authCtx.OrganizationID, postingID)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stages)
+}
+
+// CreateStage handles POST /api/recruitment-stages
+func (h *RecruitmentHandler) CreateStage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.StageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stage, err := h.service.CreateStage(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, stage)
+}
+
+// UpdateStage handles PUT /api/recruitment-stages/:id
+func (h *RecruitmentHandler) UpdateStage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.StageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stage, err := h.service.UpdateStage(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stage)
+}
+
+// DeleteStage handles DELETE /api/recruitment-stages/:id
+func (h *RecruitmentHandler) DeleteStage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteStage(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPostings handles GET /api/job-postings with optional state and
+// department_id filters
+func (h *RecruitmentHandler) ListPostings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.PostingFilter{
+		OrganizationID: authCtx.OrganizationID,
+		State:          types.PostingState(r.URL.Query().Get("state")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"department_id": &filter.DepartmentID}) {
+		return
+	}
+
+	postings, err := h.service.ListPostings(r.Context(), filter)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, postings)
+}
+
+// CreatePosting handles POST /api/job-postings
+func (h *RecruitmentHandler) CreatePosting(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.JobPostingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posting, err := h.service.CreatePosting(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, posting)
+}
+
+// GetPosting handles GET /api/job-postings/:id
+func (h *RecruitmentHandler) GetPosting(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid job posting ID", http.StatusBadRequest)
+		return
+	}
+
+	posting, err := h.service.GetPosting(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, posting)
+}
+
+// UpdatePosting handles PUT /api/job-postings/:id
+func (h *RecruitmentHandler) UpdatePosting(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid job posting ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.JobPostingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posting, err := h.service.UpdatePosting(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, posting)
+}
+
+// PublishPosting handles POST /api/job-postings/:id/publish
+func (h *RecruitmentHandler) PublishPosting(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid job posting ID", http.StatusBadRequest)
+		return
+	}
+
+	posting, err := h.service.PublishPosting(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, posting)
+}
+
+// ClosePosting handles POST /api/job-postings/:id/close
+func (h *RecruitmentHandler) ClosePosting(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid job posting ID", http.StatusBadRequest)
+		return
+	}
+
+	posting, err := h.service.ClosePosting(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, posting)
+}
+
+// ListCandidates handles GET /api/candidates with optional job_posting_id,
+// stage_id, recruiter_id, status and q filters and limit/offset paging
+func (h *RecruitmentHandler) ListCandidates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.CandidateFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.CandidateStatus(q.Get("status")),
+		Search:         q.Get("q"),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{
+		"job_posting_id": &filter.JobPostingID,
+		"stage_id":       &filter.StageID,
+		"recruiter_id":   &filter.RecruiterID,
+	}) {
+		return
+	}
+
+	candidates, err := h.service.ListCandidates(r.Context(), filter)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidates)
+}
+
+// CreateCandidate handles POST /api/candidates
+func (h *RecruitmentHandler) CreateCandidate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CandidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := h.service.CreateCandidate(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, candidate)
+}
+
+// GetCandidate handles GET /api/candidates/:id
+func (h *RecruitmentHandler) GetCandidate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := h.service.GetCandidate(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidate)
+}
+
+// UpdateCandidate handles PUT /api/candidates/:id
+func (h *RecruitmentHandler) UpdateCandidate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CandidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := h.service.UpdateCandidate(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidate)
+}
+
+// MoveCandidate handles POST /api/candidates/:id/move
+func (h *RecruitmentHandler) MoveCandidate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.service.MoveCandidate(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, change)
+}
+
+// RefuseCandidate handles POST /api/candidates/:id/refuse
+func (h *RecruitmentHandler) RefuseCandidate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RefuseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidate, err := h.service.RefuseCandidate(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, candidate)
+}
+
+// StageHistory handles GET /api/candidates/:id/stage-history
+func (h *RecruitmentHandler) StageHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.StageHistory(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// AddDocument handles POST /api/candidates/:id/documents
+func (h *RecruitmentHandler) AddDocument(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.DocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	document, err := h.service.AddDocument(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, document)
+}
+
+// DeleteDocument handles DELETE /api/candidates/:id/documents/:documentId
+func (h *RecruitmentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+	documentID, err := uuid.Parse(ps.ByName("documentId"))
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDocument(r.Context(), authCtx.OrganizationID, id, documentID); err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListActivities handles GET /api/candidates/:id/activities
+func (h *RecruitmentHandler) ListActivities(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	activities, err := h.service.ListActivities(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, activities)
+}
+
+// CreateActivity handles POST /api/candidates/:id/activities
+func (h *RecruitmentHandler) CreateActivity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.ActivityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	activity, err := h.service.CreateActivity(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, activity)
+}
+
+// ScheduleInterview handles POST /api/candidates/:id/interviews
+func (h *RecruitmentHandler) ScheduleInterview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid candidate ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.InterviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interview, err := h.service.ScheduleInterview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, interview)
+}
+
+// ListInterviews handles GET /api/interviews with optional candidate_id,
+// interviewer ("me" for the caller), state, from and to filters
+func (h *RecruitmentHandler) ListInterviews(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.InterviewFilter{
+		OrganizationID: authCtx.OrganizationID,
+		State:          types.InterviewState(q.Get("state")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{"candidate_id": &filter.CandidateID}) {
+		return
+	}
+	switch v := q.Get("interviewer"); v {
+	case "":
+	case "me":
+		filter.InterviewerID = &authCtx.UserID
+	default:
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid interviewer", http.StatusBadRequest)
+			return
+		}
+		filter.InterviewerID = &id
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	interviews, err := h.service.ListInterviews(r.Context(), filter)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, interviews)
+}
+
+// RescheduleInterview handles PUT /api/interviews/:id
+func (h *RecruitmentHandler) RescheduleInterview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid interview ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.InterviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interview, err := h.service.RescheduleInterview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, interview)
+}
+
+// CompleteInterview handles POST /api/interviews/:id/complete
+func (h *RecruitmentHandler) CompleteInterview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid interview ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.InterviewFeedback
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interview, err := h.service.CompleteInterview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, interview)
+}
+
+// CancelInterview handles POST /api/interviews/:id/cancel
+func (h *RecruitmentHandler) CancelInterview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid interview ID", http.StatusBadRequest)
+		return
+	}
+
+	interview, err := h.service.CancelInterview(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, interview)
+}
+
+// FreeSlots handles GET /api/interview-slots?interviewer=...&date=2025-04-01&duration=60,
+// listing the free slots of a day for every interviewer given. duration is
+// in minutes and defaults to an hour.
+func (h *RecruitmentHandler) FreeSlots(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var interviewerIDs []uuid.UUID
+	for _, v := range q["interviewer"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid interviewer", http.StatusBadRequest)
+			return
+		}
+		interviewerIDs = append(interviewerIDs, id)
+	}
+	day, err := time.Parse("2006-01-02", q.Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	minutes := 60
+	if v := q.Get("duration"); v != "" {
+		if minutes, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	slots, err := h.service.FreeSlots(r.Context(), authCtx.OrganizationID, interviewerIDs, day, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		writeRecruitmentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, slots)
+}
+
+// CareersPostings handles GET /public/v1/careers/:slug, listing the open
+// postings of the organization for its careers page
+func (h *RecruitmentHandler) CareersPostings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	postings, err := h.service.CareersPostings(r.Context(), ps.ByName("slug"))
+	if err != nil {
+		writePublicError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, postings)
+}
+
+// CareersPosting handles GET /public/v1/careers/:slug/postings/:posting
+func (h *RecruitmentHandler) CareersPosting(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	posting, err := h.service.CareersPosting(r.Context(), ps.ByName("slug"), ps.ByName("posting"))
+	if err != nil {
+		writePublicError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, posting)
+}
+
+// Apply handles POST /public/v1/careers/:slug/postings/:posting/apply. A
+// repeated application gets the same response as the first one.
+func (h *RecruitmentHandler) Apply(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req types.Application
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApplicationSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid application", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Apply(r.Context(), ps.ByName("slug"), ps.ByName("posting"), req); err != nil {
+		writePublicError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "received"})
+}
+
+// queryUUIDs parses optional ID query parameters
+func queryUUIDs(w http.ResponseWriter, r *http.Request, params map[string]**uuid.UUID) bool {
+	q := r.URL.Query()
+	for name, dest := range params {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return false
+			}
+			*dest = &id
+		}
+	}
+	return true
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeRecruitmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState),
+		errors.Is(err, service.ErrInterviewConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writePublicError reports careers page errors without internal detail
+func writePublicError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Careers page unavailable", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,77 @@
+package recruitment
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RecruitmentModule represents the recruitment module: job postings,
+// candidate pipelines, careers page applications and interviews
+type RecruitmentModule struct {
+	recruitmentService *service.RecruitmentService
+	recruitmentHandler *handler.RecruitmentHandler
+	logger             *slog.Logger
+}
+
+// NewRecruitmentModule creates a new recruitment module
+func NewRecruitmentModule() *RecruitmentModule {
+	return &RecruitmentModule{}
+}
+
+// Name returns the module name
+func (m *RecruitmentModule) Name() string {
+	return "recruitment"
+}
+
+// Init initializes the recruitment module
+func (m *RecruitmentModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "recruitment")
+	m.logger.Info("Initializing recruitment module")
+
+	// Create repositories
+	recruitmentRepo := repository.NewRecruitmentRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.recruitmentService = service.NewRecruitmentService(recruitmentRepo, authAdapter, deps.EventBus, m.logger)
+	m.recruitmentService.SetBusinessCalendars(calendar.NewStore(deps.DB))
+
+	// Create handlers
+	m.recruitmentHandler = handler.NewRecruitmentHandler(m.recruitmentService)
+
+	m.logger.Info("Recruitment module initialized successfully")
+	return nil
+}
+
+// RecruitmentService returns the recruitment service
+func (m *RecruitmentModule) RecruitmentService() *service.RecruitmentService {
+	return m.recruitmentService
+}
+
+// RegisterRoutes registers recruitment module routes
+func (m *RecruitmentModule) RegisterRoutes(router interface{}) {
+	if m.recruitmentHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.recruitmentHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the recruitment module
+func (m *RecruitmentModule) RegisterEventHandlers(bus interface{}) {
+	// Recruitment only publishes events, such as candidate_hired for creating the employee
+}
+
+// Health checks the health of the recruitment module
+func (m *RecruitmentModule) Health() error {
+	return nil
+}
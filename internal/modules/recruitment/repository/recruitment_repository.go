@@ -0,0 +1,925 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a posting, stage, candidate, document,
+	// interview, department or organization does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a posting slug is already used, or the
+	// person already applied to the posting
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when the candidate or stage state does not
+	// allow the change
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// activityModel is the record reference of activities scheduled on candidates
+const activityModel = "recruitment.candidate"
+
+// RecruitmentRepo defines the interface for recruitment repository operations
+type RecruitmentRepo interface {
+	ListStages(ctx context.Context, orgID uuid.UUID, postingID *uuid.UUID) ([]types.Stage, error)
+	FindStage(ctx context.Context, orgID, id uuid.UUID) (*types.Stage, error)
+	CreateStage(ctx context.Context, stage types.Stage) (*types.Stage, error)
+	UpdateStage(ctx context.Context, stage types.Stage) (*types.Stage, error)
+	DeleteStage(ctx context.Context, orgID, id uuid.UUID) error
+	ListPostings(ctx context.Context, filter types.PostingFilter) ([]types.JobPosting, error)
+	FindPosting(ctx context.Context, orgID, id uuid.UUID) (*types.JobPosting, error)
+	FindPostingBySlug(ctx context.Context, orgID uuid.UUID, slug string) (*types.JobPosting, error)
+	FindOrganizationBySlug(ctx context.Context, slug string) (uuid.UUID, error)
+	CreatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error)
+	UpdatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error)
+	ListCandidates(ctx context.Context, filter types.CandidateFilter) ([]types.Candidate, error)
+	FindCandidate(ctx context.Context, orgID, id uuid.UUID) (*types.Candidate, error)
+	FindCandidateByEmail(ctx context.Context, postingID uuid.UUID, email string) (*types.Candidate, error)
+	CreateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate, documents []types.Document) (*types.Candidate, error)
+	UpdateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate) (*types.Candidate, error)
+	MoveCandidate(ctx context.Context, change types.StageChange, hired bool) (*types.StageChange, error)
+	StageHistory(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.StageChange, error)
+	AddDocument(ctx context.Context, document types.Document) (*types.Document, error)
+	DeleteDocument(ctx context.Context, orgID, candidateID, id uuid.UUID) error
+	ListActivities(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.Activity, error)
+	CreateActivity(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity) (*types.Activity, error)
+	ListInterviews(ctx context.Context, filter types.InterviewFilter) ([]types.Interview, error)
+	FindInterview(ctx context.Context, orgID, id uuid.UUID) (*types.Interview, error)
+	CreateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error)
+	UpdateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error)
+	BusyInterviews(ctx context.Context, orgID uuid.UUID, interviewerIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]types.Interview, error)
+}
+
+// RecruitmentRepository stores job postings, their pipelines and
+// candidates, candidate documents and interviews
+type RecruitmentRepository struct {
+	db *sql.DB
+}
+
+// Ensure RecruitmentRepository implements RecruitmentRepo interface
+var _ RecruitmentRepo = &RecruitmentRepository{}
+
+func NewRecruitmentRepository(db *sql.DB) *RecruitmentRepository {
+	return &RecruitmentRepository{db: db}
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func checkDepartment(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM departments WHERE id = $1 AND organization_id = $2)
+	`, *id, orgID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check department: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("department %w", ErrNotFound)
+	}
+	return nil
+}
+
+func checkJobPosition(ctx context.Context, q queryer, orgID uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM job_positions WHERE id = $1 AND organization_id = $2)
+	`, *id, orgID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check job position: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("job position %w", ErrNotFound)
+	}
+	return nil
+}
+
+const stageColumns = `id, organization_id, job_posting_id, name, sequence, fold, hired, created_at, updated_at`
+
+func scanStage(row interface{ Scan(...interface{}) error }) (*types.Stage, error) {
+	var s types.Stage
+	var postingID uuid.NullUUID
+	if err := row.Scan(&s.ID, &s.OrganizationID, &postingID, &s.Name, &s.Sequence, &s.Fold, &s.Hired, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.JobPostingID = nullUUID(postingID)
+	return &s, nil
+}
+
+// ListStages returns the pipeline of a posting: its own stages, or the
+// organization default stages when it has none. A nil posting lists the
+// default stages.
+func (r *RecruitmentRepository) ListStages(ctx context.Context, orgID uuid.UUID, postingID *uuid.UUID) ([]types.Stage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+stageColumns+` FROM recruitment_stages
+		WHERE organization_id = $1 AND (
+			job_posting_id = $2
+			OR (job_posting_id IS NULL AND NOT EXISTS (
+				SELECT 1 FROM recruitment_stages WHERE organization_id = $1 AND job_posting_id = $2
+			))
+		)
+		ORDER BY sequence, name
+	`, orgID, postingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stages: %w", err)
+	}
+	defer rows.Close()
+
+	stages := []types.Stage{}
+	for rows.Next() {
+		s, err := scanStage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stage: %w", err)
+		}
+		stages = append(stages, *s)
+	}
+	return stages, rows.Err()
+}
+
+func (r *RecruitmentRepository) FindStage(ctx context.Context, orgID, id uuid.UUID) (*types.Stage, error) {
+	s, err := scanStage(r.db.QueryRowContext(ctx, `
+		SELECT `+stageColumns+` FROM recruitment_stages WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("stage %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find stage: %w", err)
+	}
+	return s, nil
+}
+
+func (r *RecruitmentRepository) checkStagePosting(ctx context.Context, stage types.Stage) error {
+	if stage.JobPostingID == nil {
+		return nil
+	}
+	if _, err := r.FindPosting(ctx, stage.OrganizationID, *stage.JobPostingID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *RecruitmentRepository) CreateStage(ctx context.Context, stage types.Stage) (*types.Stage, error) {
+	if err := r.checkStagePosting(ctx, stage); err != nil {
+		return nil, err
+	}
+	s, err := scanStage(r.db.QueryRowContext(ctx, `
+		INSERT INTO recruitment_stages (id, organization_id, job_posting_id, name, sequence, fold, hired)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+stageColumns,
+		uuid.New(), stage.OrganizationID, stage.JobPostingID, stage.Name, stage.Sequence, stage.Fold, stage.Hired))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stage: %w", err)
+	}
+	return s, nil
+}
+
+func (r *RecruitmentRepository) UpdateStage(ctx context.Context, stage types.Stage) (*types.Stage, error) {
+	if err := r.checkStagePosting(ctx, stage); err != nil {
+		return nil, err
+	}
+	s, err := scanStage(r.db.QueryRowContext(ctx, `
+		UPDATE recruitment_stages SET job_posting_id = $3, name = $4, sequence = $5, fold = $6, hired = $7, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+stageColumns,
+		stage.OrganizationID, stage.ID, stage.JobPostingID, stage.Name, stage.Sequence, stage.Fold, stage.Hired))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("stage %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to update stage: %w", err)
+	}
+	return s, nil
+}
+
+// DeleteStage deletes a stage no candidate is in
+func (r *RecruitmentRepository) DeleteStage(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM recruitment_stages WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("stage has candidates: %w", ErrInvalidState)
+		}
+		return fmt.Errorf("failed to delete stage: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("stage %w", ErrNotFound)
+	}
+	return nil
+}
+
+const postingColumns = `p.id, p.organization_id, p.job_position_id, p.department_id, p.title, p.slug,
+	COALESCE(p.description, ''), COALESCE(p.requirements, ''), COALESCE(p.location, ''), COALESCE(p.employment_type, ''),
+	p.state, p.recruiter_id, p.published_at, p.closes_at,
+	(SELECT COUNT(*) FROM candidates c WHERE c.job_posting_id = p.id AND c.status = 'active'),
+	p.created_at, p.updated_at`
+
+func scanPosting(row interface{ Scan(...interface{}) error }) (*types.JobPosting, error) {
+	var p types.JobPosting
+	var positionID, departmentID, recruiterID uuid.NullUUID
+	var publishedAt, closesAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.OrganizationID, &positionID, &departmentID, &p.Title, &p.Slug,
+		&p.Description, &p.Requirements, &p.Location, &p.EmploymentType,
+		&p.State, &recruiterID, &publishedAt, &closesAt, &p.Candidates, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.JobPositionID = nullUUID(positionID)
+	p.DepartmentID = nullUUID(departmentID)
+	p.RecruiterID = nullUUID(recruiterID)
+	p.PublishedAt = nullTime(publishedAt)
+	p.ClosesAt = nullTime(closesAt)
+	return &p, nil
+}
+
+func (r *RecruitmentRepository) ListPostings(ctx context.Context, filter types.PostingFilter) ([]types.JobPosting, error) {
+	where := []string{"p.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.State != "" {
+		args = append(args, string(filter.State))
+		where = append(where, fmt.Sprintf("p.state = $%d", len(args)))
+	}
+	if filter.DepartmentID != nil {
+		args = append(args, *filter.DepartmentID)
+		where = append(where, fmt.Sprintf("p.department_id = $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+postingColumns+` FROM job_postings p
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY p.published_at DESC NULLS FIRST, p.title
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list postings: %w", err)
+	}
+	defer rows.Close()
+
+	postings := []types.JobPosting{}
+	for rows.Next() {
+		p, err := scanPosting(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		postings = append(postings, *p)
+	}
+	return postings, rows.Err()
+}
+
+func (r *RecruitmentRepository) FindPosting(ctx context.Context, orgID, id uuid.UUID) (*types.JobPosting, error) {
+	p, err := scanPosting(r.db.QueryRowContext(ctx, `
+		SELECT `+postingColumns+` FROM job_postings p WHERE p.organization_id = $1 AND p.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("job posting %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find posting: %w", err)
+	}
+	return p, nil
+}
+
+func (r *RecruitmentRepository) FindPostingBySlug(ctx context.Context, orgID uuid.UUID, slug string) (*types.JobPosting, error) {
+	p, err := scanPosting(r.db.QueryRowContext(ctx, `
+		SELECT `+postingColumns+` FROM job_postings p WHERE p.organization_id = $1 AND p.slug = $2
+	`, orgID, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("job posting %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find posting: %w", err)
+	}
+	return p, nil
+}
+
+// FindOrganizationBySlug resolves the organization of a careers page
+func (r *RecruitmentRepository) FindOrganizationBySlug(ctx context.Context, slug string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM organizations WHERE slug = $1 AND deleted_at IS NULL
+	`, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("organization %w", ErrNotFound)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+	return id, nil
+}
+
+func (r *RecruitmentRepository) checkPostingReferences(ctx context.Context, posting types.JobPosting) error {
+	if err := checkDepartment(ctx, r.db, posting.OrganizationID, posting.DepartmentID); err != nil {
+		return err
+	}
+	return checkJobPosition(ctx, r.db, posting.OrganizationID, posting.JobPositionID)
+}
+
+func (r *RecruitmentRepository) CreatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error) {
+	if err := r.checkPostingReferences(ctx, posting); err != nil {
+		return nil, err
+	}
+	id := uuid.New()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO job_postings (
+			id, organization_id, job_position_id, department_id, title, slug, description, requirements,
+			location, employment_type, state, recruiter_id, published_at, closes_at, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15)
+	`, id, posting.OrganizationID, posting.JobPositionID, posting.DepartmentID, posting.Title, posting.Slug,
+		posting.Description, posting.Requirements, posting.Location, posting.EmploymentType,
+		string(posting.State), posting.RecruiterID, posting.PublishedAt, posting.ClosesAt, userID)
+	if err != nil {
+		if isUniqueViolation(err, "job_postings_slug_unique") {
+			return nil, fmt.Errorf("job posting slug %q %w", posting.Slug, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create posting: %w", err)
+	}
+	return r.FindPosting(ctx, posting.OrganizationID, id)
+}
+
+func (r *RecruitmentRepository) UpdatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error) {
+	if err := r.checkPostingReferences(ctx, posting); err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE job_postings SET
+			job_position_id = $3, department_id = $4, title = $5, slug = $6, description = $7, requirements = $8,
+			location = $9, employment_type = $10, state = $11, recruiter_id = $12, published_at = $13, closes_at = $14,
+			updated_by = $15, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+	`, posting.OrganizationID, posting.ID, posting.JobPositionID, posting.DepartmentID, posting.Title, posting.Slug,
+		posting.Description, posting.Requirements, posting.Location, posting.EmploymentType,
+		string(posting.State), posting.RecruiterID, posting.PublishedAt, posting.ClosesAt, userID)
+	if err != nil {
+		if isUniqueViolation(err, "job_postings_slug_unique") {
+			return nil, fmt.Errorf("job posting slug %q %w", posting.Slug, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update posting: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("job posting %w", ErrNotFound)
+	}
+	return r.FindPosting(ctx, posting.OrganizationID, posting.ID)
+}
+
+const candidateColumns = `id, organization_id, job_posting_id, stage_id, name, email, COALESCE(phone, ''), source,
+	recruiter_id, rating, status, COALESCE(refuse_reason, ''), COALESCE(cover_letter, ''), applied_at,
+	date_last_stage_update, hired_at, created_at, updated_at`
+
+func scanCandidate(row interface{ Scan(...interface{}) error }) (*types.Candidate, error) {
+	var c types.Candidate
+	var stageID, recruiterID uuid.NullUUID
+	var lastStageUpdate, hiredAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.OrganizationID, &c.JobPostingID, &stageID, &c.Name, &c.Email, &c.Phone, &c.Source,
+		&recruiterID, &c.Rating, &c.Status, &c.RefuseReason, &c.CoverLetter, &c.AppliedAt,
+		&lastStageUpdate, &hiredAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.StageID = nullUUID(stageID)
+	c.RecruiterID = nullUUID(recruiterID)
+	c.DateLastStageUpdate = nullTime(lastStageUpdate)
+	c.HiredAt = nullTime(hiredAt)
+	return &c, nil
+}
+
+func (r *RecruitmentRepository) ListCandidates(ctx context.Context, filter types.CandidateFilter) ([]types.Candidate, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.JobPostingID != nil {
+		args = append(args, *filter.JobPostingID)
+		where = append(where, fmt.Sprintf("job_posting_id = $%d", len(args)))
+	}
+	if filter.StageID != nil {
+		args = append(args, *filter.StageID)
+		where = append(where, fmt.Sprintf("stage_id = $%d", len(args)))
+	}
+	if filter.RecruiterID != nil {
+		args = append(args, *filter.RecruiterID)
+		where = append(where, fmt.Sprintf("recruiter_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Search != "" {
+		args = append(args, database.LikePattern(filter.Search, database.MatchModeContains))
+		where = append(where, "("+database.ILike("name", len(args))+" OR "+database.ILike("email", len(args))+")")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM candidates
+		WHERE %s
+		ORDER BY applied_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, candidateColumns, strings.Join(where, " AND "), len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []types.Candidate{}
+	for rows.Next() {
+		c, err := scanCandidate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candidate: %w", err)
+		}
+		candidates = append(candidates, *c)
+	}
+	return candidates, rows.Err()
+}
+
+// FindCandidate returns a candidate with their documents
+func (r *RecruitmentRepository) FindCandidate(ctx context.Context, orgID, id uuid.UUID) (*types.Candidate, error) {
+	c, err := scanCandidate(r.db.QueryRowContext(ctx, `
+		SELECT `+candidateColumns+` FROM candidates WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("candidate %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find candidate: %w", err)
+	}
+	if c.Documents, err = r.documents(ctx, c.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FindCandidateByEmail returns the application of an email address to a posting
+func (r *RecruitmentRepository) FindCandidateByEmail(ctx context.Context, postingID uuid.UUID, email string) (*types.Candidate, error) {
+	c, err := scanCandidate(r.db.QueryRowContext(ctx, `
+		SELECT `+candidateColumns+` FROM candidates WHERE job_posting_id = $1 AND lower(email) = lower($2)
+	`, postingID, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("candidate %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find candidate: %w", err)
+	}
+	return c, nil
+}
+
+// CreateCandidate creates a candidate with their documents
+func (r *RecruitmentRepository) CreateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate, documents []types.Document) (*types.Candidate, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id := uuid.New()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO candidates (
+			id, organization_id, job_posting_id, stage_id, name, email, phone, source, recruiter_id, rating,
+			status, cover_letter, applied_at, date_last_stage_update, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, NULLIF($12, ''), $13, $13, $14, $14)
+	`, id, candidate.OrganizationID, candidate.JobPostingID, candidate.StageID, candidate.Name, candidate.Email,
+		candidate.Phone, candidate.Source, candidate.RecruiterID, candidate.Rating, string(candidate.Status),
+		candidate.CoverLetter, candidate.AppliedAt, userID)
+	if err != nil {
+		if isUniqueViolation(err, "candidates_posting_email_unique") {
+			return nil, fmt.Errorf("application of %s %w", candidate.Email, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create candidate: %w", err)
+	}
+
+	for _, d := range documents {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO candidate_documents (id, organization_id, candidate_id, kind, name, url, uploaded_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, uuid.New(), candidate.OrganizationID, id, string(d.Kind), d.Name, d.URL, userID); err != nil {
+			return nil, fmt.Errorf("failed to add candidate document: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit candidate: %w", err)
+	}
+	return r.FindCandidate(ctx, candidate.OrganizationID, id)
+}
+
+// UpdateCandidate saves a candidate's details and status. The stage only
+// changes through MoveCandidate.
+func (r *RecruitmentRepository) UpdateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate) (*types.Candidate, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE candidates SET
+			name = $3, email = $4, phone = NULLIF($5, ''), source = $6, recruiter_id = $7, rating = $8,
+			status = $9, refuse_reason = NULLIF($10, ''), cover_letter = NULLIF($11, ''), hired_at = $12,
+			updated_by = $13, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+	`, candidate.OrganizationID, candidate.ID, candidate.Name, candidate.Email, candidate.Phone, candidate.Source,
+		candidate.RecruiterID, candidate.Rating, string(candidate.Status), candidate.RefuseReason, candidate.CoverLetter,
+		candidate.HiredAt, userID)
+	if err != nil {
+		if isUniqueViolation(err, "candidates_posting_email_unique") {
+			return nil, fmt.Errorf("application of %s %w", candidate.Email, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update candidate: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("candidate %w", ErrNotFound)
+	}
+	return r.FindCandidate(ctx, candidate.OrganizationID, candidate.ID)
+}
+
+// MoveCandidate locks the candidate, checks they are still active and in
+// the stage the change moves them from, then updates their stage, marks
+// them hired when the stage is a hired stage and records the change
+func (r *RecruitmentRepository) MoveCandidate(ctx context.Context, change types.StageChange, hired bool) (*types.StageChange, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current uuid.NullUUID
+	var status types.CandidateStatus
+	var since time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT stage_id, status, COALESCE(date_last_stage_update, applied_at)
+		FROM candidates WHERE organization_id = $1 AND id = $2
+		FOR UPDATE
+	`, change.OrganizationID, change.CandidateID).Scan(&current, &status, &since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("candidate %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock candidate: %w", err)
+	}
+	if status != types.CandidateActive {
+		return nil, fmt.Errorf("candidate is %s: %w", status, ErrInvalidState)
+	}
+	if current.Valid != (change.FromStageID != nil) || (current.Valid && current.UUID != *change.FromStageID) {
+		return nil, fmt.Errorf("candidate changed stage meanwhile: %w", ErrInvalidState)
+	}
+
+	if change.ChangedAt.After(since) {
+		change.DurationSeconds = int64(change.ChangedAt.Sub(since).Seconds())
+	}
+
+	newStatus, hiredAt := types.CandidateActive, (*time.Time)(nil)
+	if hired {
+		newStatus, hiredAt = types.CandidateHired, &change.ChangedAt
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE candidates SET stage_id = $3, date_last_stage_update = $4, status = $5, hired_at = $6,
+			updated_by = $7, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+	`, change.OrganizationID, change.CandidateID, change.ToStageID, change.ChangedAt, string(newStatus), hiredAt,
+		change.ChangedBy); err != nil {
+		return nil, fmt.Errorf("failed to move candidate: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO candidate_stage_history (
+			id, organization_id, candidate_id, from_stage_id, to_stage_id, changed_by, changed_at, duration_seconds, note
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+	`, change.ID, change.OrganizationID, change.CandidateID, change.FromStageID, change.ToStageID, change.ChangedBy,
+		change.ChangedAt, change.DurationSeconds, change.Note); err != nil {
+		return nil, fmt.Errorf("failed to record stage change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stage change: %w", err)
+	}
+	return &change, nil
+}
+
+// StageHistory lists a candidate's stage changes, oldest first
+func (r *RecruitmentRepository) StageHistory(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.StageChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, organization_id, candidate_id, from_stage_id, to_stage_id, changed_by, changed_at,
+			duration_seconds, COALESCE(note, '')
+		FROM candidate_stage_history
+		WHERE organization_id = $1 AND candidate_id = $2
+		ORDER BY changed_at, id
+	`, orgID, candidateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stage history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []types.StageChange{}
+	for rows.Next() {
+		var c types.StageChange
+		var from, to, changedBy uuid.NullUUID
+		if err := rows.Scan(&c.ID, &c.OrganizationID, &c.CandidateID, &from, &to, &changedBy, &c.ChangedAt,
+			&c.DurationSeconds, &c.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan stage change: %w", err)
+		}
+		c.FromStageID = nullUUID(from)
+		c.ToStageID = nullUUID(to)
+		c.ChangedBy = nullUUID(changedBy)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+const documentColumns = `id, organization_id, candidate_id, kind, name, url, uploaded_by, created_at`
+
+func scanDocument(row interface{ Scan(...interface{}) error }) (*types.Document, error) {
+	var d types.Document
+	var uploadedBy uuid.NullUUID
+	if err := row.Scan(&d.ID, &d.OrganizationID, &d.CandidateID, &d.Kind, &d.Name, &d.URL, &uploadedBy, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	d.UploadedBy = nullUUID(uploadedBy)
+	return &d, nil
+}
+
+func (r *RecruitmentRepository) documents(ctx context.Context, candidateID uuid.UUID) ([]types.Document, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+documentColumns+` FROM candidate_documents WHERE candidate_id = $1 ORDER BY created_at, name
+	`, candidateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candidate documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []types.Document{}
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candidate document: %w", err)
+		}
+		documents = append(documents, *d)
+	}
+	return documents, rows.Err()
+}
+
+func (r *RecruitmentRepository) AddDocument(ctx context.Context, document types.Document) (*types.Document, error) {
+	d, err := scanDocument(r.db.QueryRowContext(ctx, `
+		INSERT INTO candidate_documents (id, organization_id, candidate_id, kind, name, url, uploaded_by)
+		SELECT $1, c.organization_id, c.id, $4, $5, $6, $7
+		FROM candidates c WHERE c.organization_id = $2 AND c.id = $3
+		RETURNING `+documentColumns,
+		uuid.New(), document.OrganizationID, document.CandidateID, string(document.Kind), document.Name, document.URL,
+		document.UploadedBy))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("candidate %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to add candidate document: %w", err)
+	}
+	return d, nil
+}
+
+func (r *RecruitmentRepository) DeleteDocument(ctx context.Context, orgID, candidateID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM candidate_documents WHERE organization_id = $1 AND candidate_id = $2 AND id = $3
+	`, orgID, candidateID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete candidate document: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("document %w", ErrNotFound)
+	}
+	return nil
+}
+
+const activityColumns = `id, res_id, activity_type, summary, COALESCE(note, ''), date_deadline, assigned_to, state, done_date, created_at`
+
+func scanActivity(row interface{ Scan(...interface{}) error }) (*types.Activity, error) {
+	var a types.Activity
+	var assignedTo uuid.NullUUID
+	var deadline, doneDate sql.NullTime
+	if err := row.Scan(&a.ID, &a.CandidateID, &a.ActivityType, &a.Summary, &a.Note, &deadline, &assignedTo,
+		&a.State, &doneDate, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.DateDeadline = nullTime(deadline)
+	a.AssignedTo = nullUUID(assignedTo)
+	a.DoneDate = nullTime(doneDate)
+	return &a, nil
+}
+
+// ListActivities lists the activities scheduled on a candidate
+func (r *RecruitmentRepository) ListActivities(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.Activity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+activityColumns+` FROM activities
+		WHERE organization_id = $1 AND res_model = $2 AND res_id = $3
+		ORDER BY date_deadline NULLS LAST, created_at
+	`, orgID, activityModel, candidateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activities: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []types.Activity{}
+	for rows.Next() {
+		a, err := scanActivity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		activities = append(activities, *a)
+	}
+	return activities, rows.Err()
+}
+
+// CreateActivity schedules an activity on a candidate of the organization
+func (r *RecruitmentRepository) CreateActivity(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity) (*types.Activity, error) {
+	a, err := scanActivity(r.db.QueryRowContext(ctx, `
+		INSERT INTO activities (
+			id, organization_id, activity_type, summary, note, date_deadline, user_id, assigned_to,
+			res_model, res_id, state, created_by, updated_by
+		)
+		SELECT $1, c.organization_id, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, c.id, 'planned', $8, $8
+		FROM candidates c WHERE c.organization_id = $2 AND c.id = $3
+		RETURNING `+activityColumns,
+		uuid.New(), orgID, activity.CandidateID, activity.ActivityType, activity.Summary, activity.Note,
+		activity.DateDeadline, userID, activity.AssignedTo, activityModel))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("candidate %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to create activity: %w", err)
+	}
+	return a, nil
+}
+
+const interviewColumns = `id, organization_id, candidate_id, title, interviewer_ids, starts_at, ends_at,
+	COALESCE(location, ''), COALESCE(meeting_url, ''), state, COALESCE(feedback, ''), rating, created_at, updated_at`
+
+func scanInterview(row interface{ Scan(...interface{}) error }) (*types.Interview, error) {
+	var i types.Interview
+	var interviewers []string
+	var rating sql.NullInt64
+	if err := row.Scan(&i.ID, &i.OrganizationID, &i.CandidateID, &i.Title, pq.Array(&interviewers), &i.StartsAt, &i.EndsAt,
+		&i.Location, &i.MeetingURL, &i.State, &i.Feedback, &rating, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		return nil, err
+	}
+	i.InterviewerIDs = make([]uuid.UUID, 0, len(interviewers))
+	for _, s := range interviewers {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interviewer id %q: %w", s, err)
+		}
+		i.InterviewerIDs = append(i.InterviewerIDs, id)
+	}
+	if rating.Valid {
+		v := int(rating.Int64)
+		i.Rating = &v
+	}
+	return &i, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+func (r *RecruitmentRepository) queryInterviews(ctx context.Context, query string, args ...interface{}) ([]types.Interview, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interviews: %w", err)
+	}
+	defer rows.Close()
+
+	interviews := []types.Interview{}
+	for rows.Next() {
+		i, err := scanInterview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan interview: %w", err)
+		}
+		interviews = append(interviews, *i)
+	}
+	return interviews, rows.Err()
+}
+
+func (r *RecruitmentRepository) ListInterviews(ctx context.Context, filter types.InterviewFilter) ([]types.Interview, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.CandidateID != nil {
+		args = append(args, *filter.CandidateID)
+		where = append(where, fmt.Sprintf("candidate_id = $%d", len(args)))
+	}
+	if filter.InterviewerID != nil {
+		args = append(args, *filter.InterviewerID)
+		where = append(where, fmt.Sprintf("$%d = ANY(interviewer_ids)", len(args)))
+	}
+	if filter.State != "" {
+		args = append(args, string(filter.State))
+		where = append(where, fmt.Sprintf("state = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where = append(where, fmt.Sprintf("ends_at > $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where = append(where, fmt.Sprintf("starts_at < $%d", len(args)))
+	}
+	return r.queryInterviews(ctx, `
+		SELECT `+interviewColumns+` FROM interviews
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY starts_at, id
+	`, args...)
+}
+
+func (r *RecruitmentRepository) FindInterview(ctx context.Context, orgID, id uuid.UUID) (*types.Interview, error) {
+	i, err := scanInterview(r.db.QueryRowContext(ctx, `
+		SELECT `+interviewColumns+` FROM interviews WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("interview %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find interview: %w", err)
+	}
+	return i, nil
+}
+
+func (r *RecruitmentRepository) CreateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error) {
+	i, err := scanInterview(r.db.QueryRowContext(ctx, `
+		INSERT INTO interviews (
+			id, organization_id, candidate_id, title, interviewer_ids, starts_at, ends_at, location, meeting_url,
+			state, created_by, updated_by
+		)
+		SELECT $1, c.organization_id, c.id, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11, $11
+		FROM candidates c WHERE c.organization_id = $2 AND c.id = $3
+		RETURNING `+interviewColumns,
+		uuid.New(), interview.OrganizationID, interview.CandidateID, interview.Title, pq.Array(uuidStrings(interview.InterviewerIDs)),
+		interview.StartsAt, interview.EndsAt, interview.Location, interview.MeetingURL, string(interview.State), userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("candidate %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to create interview: %w", err)
+	}
+	return i, nil
+}
+
+func (r *RecruitmentRepository) UpdateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error) {
+	i, err := scanInterview(r.db.QueryRowContext(ctx, `
+		UPDATE interviews SET
+			title = $3, interviewer_ids = $4, starts_at = $5, ends_at = $6, location = NULLIF($7, ''),
+			meeting_url = NULLIF($8, ''), state = $9, feedback = NULLIF($10, ''), rating = $11,
+			updated_by = $12, updated_at = now()
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+interviewColumns,
+		interview.OrganizationID, interview.ID, interview.Title, pq.Array(uuidStrings(interview.InterviewerIDs)),
+		interview.StartsAt, interview.EndsAt, interview.Location, interview.MeetingURL, string(interview.State),
+		interview.Feedback, interview.Rating, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("interview %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to update interview: %w", err)
+	}
+	return i, nil
+}
+
+// BusyInterviews returns the scheduled interviews of any of the
+// interviewers overlapping the period, leaving out excludeID
+func (r *RecruitmentRepository) BusyInterviews(ctx context.Context, orgID uuid.UUID, interviewerIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]types.Interview, error) {
+	return r.queryInterviews(ctx, `
+		SELECT `+interviewColumns+` FROM interviews
+		WHERE organization_id = $1 AND state = 'scheduled'
+			AND interviewer_ids && $2::uuid[]
+			AND starts_at < $4 AND ends_at > $3
+			AND ($5::uuid IS NULL OR id <> $5)
+		ORDER BY starts_at, id
+	`, orgID, pq.Array(uuidStrings(interviewerIDs)), from, to, excludeID)
+}
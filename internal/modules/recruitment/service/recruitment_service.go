@@ -0,0 +1,1082 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPageSize is the page size of candidate lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of candidate lists
+	MaxPageSize = 500
+	// MaxInterviewDuration bounds how long an interview can last
+	MaxInterviewDuration = 8 * time.Hour
+	// SlotStep is the spacing of the free interview slots offered
+	SlotStep = 15 * time.Minute
+	// EventApplicationReceived is published when a careers page application creates a candidate
+	EventApplicationReceived = "recruitment.application_received"
+	// EventCandidateStageChanged is published when a candidate moves stage
+	EventCandidateStageChanged = "recruitment.candidate_stage_changed"
+	// EventCandidateHired is published when a candidate moves to a hired stage
+	EventCandidateHired = "recruitment.candidate_hired"
+	// EventInterviewScheduled is published when an interview is scheduled or rescheduled
+	EventInterviewScheduled = "recruitment.interview_scheduled"
+	// EventInterviewCancelled is published when an interview is cancelled
+	EventInterviewCancelled = "recruitment.interview_cancelled"
+)
+
+var (
+	// ErrInvalid wraps validation failures of postings, stages, candidates
+	// and interviews
+	ErrInvalid = errors.New("invalid request")
+	// ErrInterviewConflict is returned when an interviewer is already booked
+	// into another interview at the time
+	ErrInterviewConflict = errors.New("interviewer already has an interview at this time")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// activityTypes are the activity types of the shared activities table
+var activityTypes = map[string]bool{"call": true, "meeting": true, "email": true, "todo": true, "note": true}
+
+// AuthService defines the permission check used by the recruitment service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// BusinessCalendarResolver resolves the business calendar interviews are
+// scheduled against
+type BusinessCalendarResolver interface {
+	Resolve(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*calendar.Calendar, error)
+}
+
+// RecruitmentService runs job postings and their candidate pipelines: it
+// takes careers page applications, moves candidates through stages and
+// schedules interviews within business hours
+type RecruitmentService struct {
+	repo        repository.RecruitmentRepo
+	authService AuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	calendars   BusinessCalendarResolver
+	now         func() time.Time
+}
+
+func NewRecruitmentService(repo repository.RecruitmentRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *RecruitmentService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RecruitmentService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetBusinessCalendars makes interview scheduling respect the organization's
+// business hours and holidays
+func (s *RecruitmentService) SetBusinessCalendars(calendars BusinessCalendarResolver) {
+	s.calendars = calendars
+}
+
+func (s *RecruitmentService) calendar(ctx context.Context, orgID uuid.UUID) (*calendar.Calendar, error) {
+	if s.calendars == nil {
+		return calendar.Always(), nil
+	}
+	cal, err := s.calendars.Resolve(ctx, orgID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load business calendar: %w", err)
+	}
+	return cal, nil
+}
+
+func (s *RecruitmentService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish recruitment event", "event", eventType, "error", err)
+		}
+	}
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// slugify derives a careers page slug from a title
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 100 {
+		slug = strings.TrimSuffix(slug[:100], "-")
+	}
+	return slug
+}
+
+func validateEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", fmt.Errorf("%w: email is required", ErrInvalid)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("%w: %q is not a valid email address", ErrInvalid, email)
+	}
+	return email, nil
+}
+
+func validateURL(field, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: %s must be an http or https URL", ErrInvalid, field)
+	}
+	return raw, nil
+}
+
+// ListStages returns the pipeline of a posting, or the default pipeline
+func (s *RecruitmentService) ListStages(ctx context.Context, orgID uuid.UUID, postingID *uuid.UUID) ([]types.Stage, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListStages(ctx, orgID, postingID)
+}
+
+func validateStage(request *types.StageRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	return nil
+}
+
+// CreateStage adds a stage to the default pipeline or a posting's own
+func (s *RecruitmentService) CreateStage(ctx context.Context, orgID uuid.UUID, request types.StageRequest) (*types.Stage, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateStage(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateStage(ctx, types.Stage{
+		OrganizationID: orgID,
+		JobPostingID:   request.JobPostingID,
+		Name:           request.Name,
+		Sequence:       request.Sequence,
+		Fold:           request.Fold,
+		Hired:          request.Hired,
+	})
+}
+
+// UpdateStage replaces a stage
+func (s *RecruitmentService) UpdateStage(ctx context.Context, orgID, id uuid.UUID, request types.StageRequest) (*types.Stage, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateStage(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateStage(ctx, types.Stage{
+		ID:             id,
+		OrganizationID: orgID,
+		JobPostingID:   request.JobPostingID,
+		Name:           request.Name,
+		Sequence:       request.Sequence,
+		Fold:           request.Fold,
+		Hired:          request.Hired,
+	})
+}
+
+// DeleteStage deletes a stage no candidate is in
+func (s *RecruitmentService) DeleteStage(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteStage(ctx, orgID, id)
+}
+
+// ListPostings returns the postings of an organization
+func (s *RecruitmentService) ListPostings(ctx context.Context, filter types.PostingFilter) ([]types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	return s.repo.ListPostings(ctx, filter)
+}
+
+// GetPosting returns a posting
+func (s *RecruitmentService) GetPosting(ctx context.Context, orgID, id uuid.UUID) (*types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindPosting(ctx, orgID, id)
+}
+
+func validatePosting(request *types.JobPostingRequest) error {
+	request.Title = strings.TrimSpace(request.Title)
+	if request.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	request.Slug = strings.TrimSpace(request.Slug)
+	if request.Slug == "" {
+		request.Slug = slugify(request.Title)
+	}
+	if len(request.Slug) > 100 || !slugPattern.MatchString(request.Slug) {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and dashes", ErrInvalid)
+	}
+	request.Location = strings.TrimSpace(request.Location)
+	request.EmploymentType = strings.TrimSpace(request.EmploymentType)
+	return nil
+}
+
+func applyPostingRequest(posting *types.JobPosting, request types.JobPostingRequest) {
+	posting.JobPositionID = request.JobPositionID
+	posting.DepartmentID = request.DepartmentID
+	posting.Title = request.Title
+	posting.Slug = request.Slug
+	posting.Description = request.Description
+	posting.Requirements = request.Requirements
+	posting.Location = request.Location
+	posting.EmploymentType = request.EmploymentType
+	posting.RecruiterID = request.RecruiterID
+	posting.ClosesAt = request.ClosesAt
+}
+
+// CreatePosting creates a draft posting
+func (s *RecruitmentService) CreatePosting(ctx context.Context, orgID, userID uuid.UUID, request types.JobPostingRequest) (*types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validatePosting(&request); err != nil {
+		return nil, err
+	}
+	posting := types.JobPosting{OrganizationID: orgID, State: types.PostingDraft}
+	applyPostingRequest(&posting, request)
+	return s.repo.CreatePosting(ctx, &userID, posting)
+}
+
+// UpdatePosting replaces the details of a posting, keeping its state
+func (s *RecruitmentService) UpdatePosting(ctx context.Context, orgID, userID, id uuid.UUID, request types.JobPostingRequest) (*types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validatePosting(&request); err != nil {
+		return nil, err
+	}
+	posting, err := s.repo.FindPosting(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyPostingRequest(posting, request)
+	return s.repo.UpdatePosting(ctx, &userID, *posting)
+}
+
+// PublishPosting lists a posting on the careers page. Its pipeline needs
+// a stage for new candidates to start in.
+func (s *RecruitmentService) PublishPosting(ctx context.Context, orgID, userID, id uuid.UUID) (*types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	posting, err := s.repo.FindPosting(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if posting.State == types.PostingPublished {
+		return posting, nil
+	}
+	now := s.now()
+	if posting.ClosesAt != nil && !posting.ClosesAt.After(now) {
+		return nil, fmt.Errorf("%w: closes_at is in the past", ErrInvalid)
+	}
+	if _, err := s.firstStage(ctx, orgID, posting.ID); err != nil {
+		return nil, err
+	}
+
+	posting.State = types.PostingPublished
+	if posting.PublishedAt == nil {
+		posting.PublishedAt = &now
+	}
+	return s.repo.UpdatePosting(ctx, &userID, *posting)
+}
+
+// ClosePosting stops a posting taking applications
+func (s *RecruitmentService) ClosePosting(ctx context.Context, orgID, userID, id uuid.UUID) (*types.JobPosting, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	posting, err := s.repo.FindPosting(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if posting.State == types.PostingClosed {
+		return posting, nil
+	}
+	posting.State = types.PostingClosed
+	return s.repo.UpdatePosting(ctx, &userID, *posting)
+}
+
+// firstStage is the stage new candidates of a posting start in: the first
+// stage of its pipeline that is not a hired stage
+func (s *RecruitmentService) firstStage(ctx context.Context, orgID, postingID uuid.UUID) (*types.Stage, error) {
+	stages, err := s.repo.ListStages(ctx, orgID, &postingID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stages {
+		if !stages[i].Hired {
+			return &stages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: the posting's pipeline has no stages", ErrInvalid)
+}
+
+// pipelineStage returns the stage if it belongs to the posting's pipeline
+func (s *RecruitmentService) pipelineStage(ctx context.Context, orgID, postingID, stageID uuid.UUID) (*types.Stage, error) {
+	stages, err := s.repo.ListStages(ctx, orgID, &postingID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stages {
+		if stages[i].ID == stageID {
+			return &stages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: stage is not in the posting's pipeline", ErrInvalid)
+}
+
+func publicPosting(p types.JobPosting) types.PublicPosting {
+	return types.PublicPosting{
+		Title:          p.Title,
+		Slug:           p.Slug,
+		Description:    p.Description,
+		Requirements:   p.Requirements,
+		Location:       p.Location,
+		EmploymentType: p.EmploymentType,
+		PublishedAt:    p.PublishedAt,
+		ClosesAt:       p.ClosesAt,
+	}
+}
+
+// CareersPostings returns the open postings of the organization with the
+// slug, for its careers page. It needs no permission.
+func (s *RecruitmentService) CareersPostings(ctx context.Context, orgSlug string) ([]types.PublicPosting, error) {
+	orgID, err := s.repo.FindOrganizationBySlug(ctx, orgSlug)
+	if err != nil {
+		return nil, err
+	}
+	postings, err := s.repo.ListPostings(ctx, types.PostingFilter{OrganizationID: orgID, State: types.PostingPublished})
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	public := []types.PublicPosting{}
+	for _, p := range postings {
+		if p.IsOpen(now) {
+			public = append(public, publicPosting(p))
+		}
+	}
+	return public, nil
+}
+
+// openPosting finds a posting accepting applications on a careers page.
+// Drafts and closed postings are reported as not found.
+func (s *RecruitmentService) openPosting(ctx context.Context, orgSlug, slug string) (*types.JobPosting, error) {
+	orgID, err := s.repo.FindOrganizationBySlug(ctx, orgSlug)
+	if err != nil {
+		return nil, err
+	}
+	posting, err := s.repo.FindPostingBySlug(ctx, orgID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if !posting.IsOpen(s.now()) {
+		return nil, fmt.Errorf("job posting %w", repository.ErrNotFound)
+	}
+	return posting, nil
+}
+
+// CareersPosting returns an open posting for the careers page
+func (s *RecruitmentService) CareersPosting(ctx context.Context, orgSlug, slug string) (*types.PublicPosting, error) {
+	posting, err := s.openPosting(ctx, orgSlug, slug)
+	if err != nil {
+		return nil, err
+	}
+	public := publicPosting(*posting)
+	return &public, nil
+}
+
+func validateApplication(app *types.Application) error {
+	app.Name = strings.TrimSpace(app.Name)
+	if app.Name == "" || len(app.Name) > 255 {
+		return fmt.Errorf("%w: name is required and at most 255 characters", ErrInvalid)
+	}
+	email, err := validateEmail(app.Email)
+	if err != nil {
+		return err
+	}
+	app.Email = email
+	app.Phone = strings.TrimSpace(app.Phone)
+	if len(app.Phone) > 50 {
+		return fmt.Errorf("%w: phone is at most 50 characters", ErrInvalid)
+	}
+	app.CoverLetter = strings.TrimSpace(app.CoverLetter)
+	if len(app.CoverLetter) > 20000 {
+		return fmt.Errorf("%w: cover letter is at most 20000 characters", ErrInvalid)
+	}
+	if app.ResumeURL != "" {
+		if app.ResumeURL, err = validateURL("resume_url", app.ResumeURL); err != nil {
+			return err
+		}
+	}
+	if app.PortfolioURL != "" {
+		if app.PortfolioURL, err = validateURL("portfolio_url", app.PortfolioURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply takes a careers page application. The candidate starts in the
+// first stage of the posting's pipeline with its recruiter. A repeated
+// application of the same email is accepted without creating another
+// candidate, so applicants cannot tell whether an address already applied.
+// It needs no permission.
+func (s *RecruitmentService) Apply(ctx context.Context, orgSlug, slug string, app types.Application) error {
+	if err := validateApplication(&app); err != nil {
+		return err
+	}
+	posting, err := s.openPosting(ctx, orgSlug, slug)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.repo.FindCandidateByEmail(ctx, posting.ID, app.Email); err == nil {
+		s.logger.Info("Ignored repeated application", "job_posting_id", posting.ID)
+		return nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	stage, err := s.firstStage(ctx, posting.OrganizationID, posting.ID)
+	if err != nil {
+		return err
+	}
+
+	var documents []types.Document
+	if app.ResumeURL != "" {
+		documents = append(documents, types.Document{Kind: types.DocumentResume, Name: "Resume", URL: app.ResumeURL})
+	}
+	if app.PortfolioURL != "" {
+		documents = append(documents, types.Document{Kind: types.DocumentPortfolio, Name: "Portfolio", URL: app.PortfolioURL})
+	}
+
+	candidate, err := s.repo.CreateCandidate(ctx, nil, types.Candidate{
+		OrganizationID: posting.OrganizationID,
+		JobPostingID:   posting.ID,
+		StageID:        &stage.ID,
+		Name:           app.Name,
+		Email:          app.Email,
+		Phone:          app.Phone,
+		Source:         types.SourceCareersPage,
+		RecruiterID:    posting.RecruiterID,
+		Status:         types.CandidateActive,
+		CoverLetter:    app.CoverLetter,
+		AppliedAt:      s.now(),
+	}, documents)
+	if errors.Is(err, repository.ErrDuplicate) {
+		// A concurrent submission of the same application won the race
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publishEvent(ctx, EventApplicationReceived, candidate)
+	return nil
+}
+
+// ListCandidates returns a page of candidates
+func (s *RecruitmentService) ListCandidates(ctx context.Context, filter types.CandidateFilter) ([]types.Candidate, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Search = strings.TrimSpace(filter.Search)
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListCandidates(ctx, filter)
+}
+
+// GetCandidate returns a candidate with their documents
+func (s *RecruitmentService) GetCandidate(ctx context.Context, orgID, id uuid.UUID) (*types.Candidate, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindCandidate(ctx, orgID, id)
+}
+
+func validateCandidate(request *types.CandidateRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	email, err := validateEmail(request.Email)
+	if err != nil {
+		return err
+	}
+	request.Email = email
+	request.Phone = strings.TrimSpace(request.Phone)
+	request.Source = strings.TrimSpace(request.Source)
+	if request.Source == "" {
+		request.Source = "manual"
+	}
+	if request.Rating < 0 || request.Rating > 5 {
+		return fmt.Errorf("%w: rating must be between 0 and 5", ErrInvalid)
+	}
+	return nil
+}
+
+// CreateCandidate adds a candidate by hand, in the requested stage or the
+// first stage of the posting's pipeline
+func (s *RecruitmentService) CreateCandidate(ctx context.Context, orgID, userID uuid.UUID, request types.CandidateRequest) (*types.Candidate, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCandidate(&request); err != nil {
+		return nil, err
+	}
+	posting, err := s.repo.FindPosting(ctx, orgID, request.JobPostingID)
+	if err != nil {
+		return nil, err
+	}
+	if posting.State == types.PostingClosed {
+		return nil, fmt.Errorf("%w: job posting is closed", ErrInvalid)
+	}
+
+	var stage *types.Stage
+	if request.StageID != nil {
+		stage, err = s.pipelineStage(ctx, orgID, posting.ID, *request.StageID)
+		if err == nil && stage.Hired {
+			err = fmt.Errorf("%w: candidates are hired by moving them to a hired stage", ErrInvalid)
+		}
+	} else {
+		stage, err = s.firstStage(ctx, orgID, posting.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recruiterID := request.RecruiterID
+	if recruiterID == nil {
+		recruiterID = posting.RecruiterID
+	}
+	return s.repo.CreateCandidate(ctx, &userID, types.Candidate{
+		OrganizationID: orgID,
+		JobPostingID:   posting.ID,
+		StageID:        &stage.ID,
+		Name:           request.Name,
+		Email:          request.Email,
+		Phone:          request.Phone,
+		Source:         request.Source,
+		RecruiterID:    recruiterID,
+		Rating:         request.Rating,
+		Status:         types.CandidateActive,
+		CoverLetter:    request.CoverLetter,
+		AppliedAt:      s.now(),
+	}, nil)
+}
+
+// UpdateCandidate replaces a candidate's details. The posting and stage
+// are left alone; candidates change stage through MoveCandidate.
+func (s *RecruitmentService) UpdateCandidate(ctx context.Context, orgID, userID, id uuid.UUID, request types.CandidateRequest) (*types.Candidate, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateCandidate(&request); err != nil {
+		return nil, err
+	}
+	candidate, err := s.repo.FindCandidate(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	candidate.Name = request.Name
+	candidate.Email = request.Email
+	candidate.Phone = request.Phone
+	candidate.Source = request.Source
+	candidate.RecruiterID = request.RecruiterID
+	candidate.Rating = request.Rating
+	candidate.CoverLetter = request.CoverLetter
+	return s.repo.UpdateCandidate(ctx, &userID, *candidate)
+}
+
+// MoveCandidate moves an active candidate to another stage of their
+// posting's pipeline. Moving them to a hired stage marks them hired.
+func (s *RecruitmentService) MoveCandidate(ctx context.Context, orgID, userID, id uuid.UUID, request types.MoveRequest) (*types.StageChange, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	candidate, err := s.repo.FindCandidate(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Status != types.CandidateActive {
+		return nil, fmt.Errorf("candidate is %s: %w", candidate.Status, repository.ErrInvalidState)
+	}
+	if candidate.StageID != nil && *candidate.StageID == request.StageID {
+		return nil, fmt.Errorf("%w: candidate is already in this stage", ErrInvalid)
+	}
+	stage, err := s.pipelineStage(ctx, orgID, candidate.JobPostingID, request.StageID)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := s.repo.MoveCandidate(ctx, types.StageChange{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		CandidateID:    candidate.ID,
+		FromStageID:    candidate.StageID,
+		ToStageID:      &stage.ID,
+		ChangedBy:      &userID,
+		ChangedAt:      s.now(),
+		Note:           strings.TrimSpace(request.Note),
+	}, stage.Hired)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventCandidateStageChanged, change)
+
+	if stage.Hired {
+		event := types.HiredEvent{
+			OrganizationID: orgID,
+			CandidateID:    candidate.ID,
+			JobPostingID:   candidate.JobPostingID,
+			Name:           candidate.Name,
+			Email:          candidate.Email,
+			Phone:          candidate.Phone,
+			HiredAt:        change.ChangedAt,
+		}
+		if posting, err := s.repo.FindPosting(ctx, orgID, candidate.JobPostingID); err == nil {
+			event.JobPositionID = posting.JobPositionID
+			event.DepartmentID = posting.DepartmentID
+		}
+		s.publishEvent(ctx, EventCandidateHired, event)
+		s.logger.Info("Candidate hired", "candidate_id", candidate.ID, "job_posting_id", candidate.JobPostingID)
+	}
+	return change, nil
+}
+
+// RefuseCandidate takes an active candidate out of the running, refused or
+// withdrawn, and cancels their scheduled interviews
+func (s *RecruitmentService) RefuseCandidate(ctx context.Context, orgID, userID, id uuid.UUID, request types.RefuseRequest) (*types.Candidate, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	candidate, err := s.repo.FindCandidate(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Status != types.CandidateActive {
+		return nil, fmt.Errorf("candidate is %s: %w", candidate.Status, repository.ErrInvalidState)
+	}
+
+	candidate.Status = types.CandidateRefused
+	if request.Withdrawn {
+		candidate.Status = types.CandidateWithdrawn
+	}
+	candidate.RefuseReason = strings.TrimSpace(request.Reason)
+	updated, err := s.repo.UpdateCandidate(ctx, &userID, *candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	interviews, err := s.repo.ListInterviews(ctx, types.InterviewFilter{
+		OrganizationID: orgID,
+		CandidateID:    &candidate.ID,
+		State:          types.InterviewScheduled,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list interviews of refused candidate", "candidate_id", candidate.ID, "error", err)
+		return updated, nil
+	}
+	for _, interview := range interviews {
+		if _, err := s.cancelInterview(ctx, &userID, interview); err != nil {
+			s.logger.Error("Failed to cancel interview of refused candidate", "interview_id", interview.ID, "error", err)
+		}
+	}
+	return updated, nil
+}
+
+// StageHistory lists a candidate's stage changes, oldest first
+func (s *RecruitmentService) StageHistory(ctx context.Context, orgID, id uuid.UUID) ([]types.StageChange, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if _, err := s.repo.FindCandidate(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.StageHistory(ctx, orgID, id)
+}
+
+// AddDocument attaches a document to a candidate
+func (s *RecruitmentService) AddDocument(ctx context.Context, orgID, userID, candidateID uuid.UUID, request types.DocumentRequest) (*types.Document, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.Kind == "" {
+		request.Kind = types.DocumentOther
+	}
+	if !request.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: unknown document kind %q", ErrInvalid, request.Kind)
+	}
+	documentURL, err := validateURL("url", request.URL)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = string(request.Kind)
+	}
+	return s.repo.AddDocument(ctx, types.Document{
+		OrganizationID: orgID,
+		CandidateID:    candidateID,
+		Kind:           request.Kind,
+		Name:           name,
+		URL:            documentURL,
+		UploadedBy:     &userID,
+	})
+}
+
+// DeleteDocument removes a document from a candidate
+func (s *RecruitmentService) DeleteDocument(ctx context.Context, orgID, candidateID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteDocument(ctx, orgID, candidateID, id)
+}
+
+// ListActivities lists the activities scheduled on a candidate
+func (s *RecruitmentService) ListActivities(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.Activity, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListActivities(ctx, orgID, candidateID)
+}
+
+// CreateActivity schedules an activity on a candidate
+func (s *RecruitmentService) CreateActivity(ctx context.Context, orgID, userID, candidateID uuid.UUID, request types.ActivityRequest) (*types.Activity, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !activityTypes[request.ActivityType] {
+		return nil, fmt.Errorf("%w: activity type must be call, meeting, email, todo or note", ErrInvalid)
+	}
+	summary := strings.TrimSpace(request.Summary)
+	if summary == "" {
+		return nil, fmt.Errorf("%w: summary is required", ErrInvalid)
+	}
+	assignedTo := request.AssignedTo
+	if assignedTo == nil {
+		assignedTo = &userID
+	}
+	return s.repo.CreateActivity(ctx, orgID, &userID, types.Activity{
+		CandidateID:  candidateID,
+		ActivityType: request.ActivityType,
+		Summary:      summary,
+		Note:         strings.TrimSpace(request.Note),
+		DateDeadline: request.DateDeadline,
+		AssignedTo:   assignedTo,
+	})
+}
+
+// ListInterviews returns interviews, by default those still to come
+func (s *RecruitmentService) ListInterviews(ctx context.Context, filter types.InterviewFilter) ([]types.Interview, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalid, filter.State)
+	}
+	return s.repo.ListInterviews(ctx, filter)
+}
+
+func uniqueInterviewers(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if id != uuid.Nil && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// checkSlot checks an interview period is in the future, within business
+// hours and free for every interviewer
+func (s *RecruitmentService) checkSlot(ctx context.Context, orgID uuid.UUID, interviewerIDs []uuid.UUID, start, end time.Time, excludeID *uuid.UUID) error {
+	if !end.After(start) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+	}
+	if end.Sub(start) > MaxInterviewDuration {
+		return fmt.Errorf("%w: an interview lasts at most %s", ErrInvalid, MaxInterviewDuration)
+	}
+	if !start.After(s.now()) {
+		return fmt.Errorf("%w: interviews are scheduled in the future", ErrInvalid)
+	}
+
+	cal, err := s.calendar(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if name, ok := cal.Holiday(start); ok {
+		return fmt.Errorf("%w: %s is a holiday (%s)", ErrInvalid, start.In(cal.Location()).Format("2006-01-02"), name)
+	}
+	if cal.Between(start, end) < end.Sub(start) {
+		return fmt.Errorf("%w: the interview is outside business hours", ErrInvalid)
+	}
+
+	busy, err := s.repo.BusyInterviews(ctx, orgID, interviewerIDs, start, end, excludeID)
+	if err != nil {
+		return err
+	}
+	if len(busy) > 0 {
+		return fmt.Errorf("%w: %s from %s", ErrInterviewConflict, busy[0].Title, busy[0].StartsAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func validateInterview(request *types.InterviewRequest) error {
+	request.Title = strings.TrimSpace(request.Title)
+	if request.Title == "" {
+		request.Title = "Interview"
+	}
+	request.InterviewerIDs = uniqueInterviewers(request.InterviewerIDs)
+	if len(request.InterviewerIDs) == 0 {
+		return fmt.Errorf("%w: an interview needs an interviewer", ErrInvalid)
+	}
+	if request.MeetingURL != "" {
+		meetingURL, err := validateURL("meeting_url", request.MeetingURL)
+		if err != nil {
+			return err
+		}
+		request.MeetingURL = meetingURL
+	}
+	request.Location = strings.TrimSpace(request.Location)
+	return nil
+}
+
+// ScheduleInterview books an interview of an active candidate within
+// business hours when every interviewer is free
+func (s *RecruitmentService) ScheduleInterview(ctx context.Context, orgID, userID, candidateID uuid.UUID, request types.InterviewRequest) (*types.Interview, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateInterview(&request); err != nil {
+		return nil, err
+	}
+	candidate, err := s.repo.FindCandidate(ctx, orgID, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Status != types.CandidateActive {
+		return nil, fmt.Errorf("candidate is %s: %w", candidate.Status, repository.ErrInvalidState)
+	}
+	if err := s.checkSlot(ctx, orgID, request.InterviewerIDs, request.StartsAt, request.EndsAt, nil); err != nil {
+		return nil, err
+	}
+
+	interview, err := s.repo.CreateInterview(ctx, &userID, types.Interview{
+		OrganizationID: orgID,
+		CandidateID:    candidate.ID,
+		Title:          request.Title,
+		InterviewerIDs: request.InterviewerIDs,
+		StartsAt:       request.StartsAt,
+		EndsAt:         request.EndsAt,
+		Location:       request.Location,
+		MeetingURL:     request.MeetingURL,
+		State:          types.InterviewScheduled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventInterviewScheduled, interview)
+	return interview, nil
+}
+
+// RescheduleInterview moves a scheduled interview or changes its interviewers
+func (s *RecruitmentService) RescheduleInterview(ctx context.Context, orgID, userID, id uuid.UUID, request types.InterviewRequest) (*types.Interview, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateInterview(&request); err != nil {
+		return nil, err
+	}
+	interview, err := s.repo.FindInterview(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if interview.State != types.InterviewScheduled {
+		return nil, fmt.Errorf("interview is %s: %w", interview.State, repository.ErrInvalidState)
+	}
+	if err := s.checkSlot(ctx, orgID, request.InterviewerIDs, request.StartsAt, request.EndsAt, &interview.ID); err != nil {
+		return nil, err
+	}
+
+	interview.Title = request.Title
+	interview.InterviewerIDs = request.InterviewerIDs
+	interview.StartsAt = request.StartsAt
+	interview.EndsAt = request.EndsAt
+	interview.Location = request.Location
+	interview.MeetingURL = request.MeetingURL
+	updated, err := s.repo.UpdateInterview(ctx, &userID, *interview)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventInterviewScheduled, updated)
+	return updated, nil
+}
+
+// CompleteInterview records the feedback of an interview that took place
+func (s *RecruitmentService) CompleteInterview(ctx context.Context, orgID, userID, id uuid.UUID, feedback types.InterviewFeedback) (*types.Interview, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if feedback.Rating != nil && (*feedback.Rating < 0 || *feedback.Rating > 5) {
+		return nil, fmt.Errorf("%w: rating must be between 0 and 5", ErrInvalid)
+	}
+	interview, err := s.repo.FindInterview(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if interview.State != types.InterviewScheduled {
+		return nil, fmt.Errorf("interview is %s: %w", interview.State, repository.ErrInvalidState)
+	}
+	if interview.StartsAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: the interview has not started yet", ErrInvalid)
+	}
+
+	interview.State = types.InterviewCompleted
+	interview.Feedback = strings.TrimSpace(feedback.Feedback)
+	interview.Rating = feedback.Rating
+	return s.repo.UpdateInterview(ctx, &userID, *interview)
+}
+
+// CancelInterview cancels a scheduled interview
+func (s *RecruitmentService) CancelInterview(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Interview, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment_candidates:update"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	interview, err := s.repo.FindInterview(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if interview.State != types.InterviewScheduled {
+		return nil, fmt.Errorf("interview is %s: %w", interview.State, repository.ErrInvalidState)
+	}
+	return s.cancelInterview(ctx, &userID, *interview)
+}
+
+func (s *RecruitmentService) cancelInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error) {
+	interview.State = types.InterviewCancelled
+	updated, err := s.repo.UpdateInterview(ctx, userID, interview)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventInterviewCancelled, updated)
+	return updated, nil
+}
+
+// FreeSlots lists the interview slots of the duration on a day, in the
+// business calendar's time zone, that are within business hours, still to
+// come and free for every interviewer
+func (s *RecruitmentService) FreeSlots(ctx context.Context, orgID uuid.UUID, interviewerIDs []uuid.UUID, day time.Time, duration time.Duration) ([]types.Slot, error) {
+	if err := s.authService.CheckPermission(ctx, "recruitment:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	interviewerIDs = uniqueInterviewers(interviewerIDs)
+	if len(interviewerIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one interviewer is required", ErrInvalid)
+	}
+	if duration <= 0 || duration > MaxInterviewDuration {
+		return nil, fmt.Errorf("%w: duration must be positive and at most %s", ErrInvalid, MaxInterviewDuration)
+	}
+
+	cal, err := s.calendar(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	loc := cal.Location()
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	busy, err := s.repo.BusyInterviews(ctx, orgID, interviewerIDs, dayStart, dayEnd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	slots := []types.Slot{}
+	t, ok := cal.NextOpen(dayStart)
+	for ok && t.Before(dayEnd) {
+		end := t.Add(duration)
+		if end.After(dayEnd) {
+			break
+		}
+		if t.After(now) && cal.Between(t, end) >= duration && !overlapsAny(busy, t, end) {
+			slots = append(slots, types.Slot{StartsAt: t, EndsAt: end})
+		}
+		t = t.Add(SlotStep)
+		if !cal.IsOpen(t) {
+			t, ok = cal.NextOpen(t)
+		}
+	}
+	return slots, nil
+}
+
+func overlapsAny(interviews []types.Interview, start, end time.Time) bool {
+	for _, i := range interviews {
+		if i.StartsAt.Before(end) && i.EndsAt.After(start) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/recruitment/types"
+	"github.com/KevTiv/alieze-erp/pkg/calendar"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRecruitmentRepo struct {
+	orgs       map[string]uuid.UUID
+	stages     []types.Stage
+	postings   []types.JobPosting
+	candidates []types.Candidate
+	documents  []types.Document
+	moves      []types.StageChange
+	interviews []types.Interview
+}
+
+func (f *fakeRecruitmentRepo) ListStages(ctx context.Context, orgID uuid.UUID, postingID *uuid.UUID) ([]types.Stage, error) {
+	var own, defaults []types.Stage
+	for _, s := range f.stages {
+		switch {
+		case s.JobPostingID == nil:
+			defaults = append(defaults, s)
+		case postingID != nil && *s.JobPostingID == *postingID:
+			own = append(own, s)
+		}
+	}
+	if len(own) > 0 {
+		return own, nil
+	}
+	return defaults, nil
+}
+
+func (f *fakeRecruitmentRepo) FindStage(ctx context.Context, orgID, id uuid.UUID) (*types.Stage, error) {
+	for i := range f.stages {
+		if f.stages[i].ID == id {
+			return &f.stages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("stage %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) CreateStage(ctx context.Context, stage types.Stage) (*types.Stage, error) {
+	return &stage, nil
+}
+
+func (f *fakeRecruitmentRepo) UpdateStage(ctx context.Context, stage types.Stage) (*types.Stage, error) {
+	return &stage, nil
+}
+
+func (f *fakeRecruitmentRepo) DeleteStage(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeRecruitmentRepo) ListPostings(ctx context.Context, filter types.PostingFilter) ([]types.JobPosting, error) {
+	var postings []types.JobPosting
+	for _, p := range f.postings {
+		if filter.State == "" || p.State == filter.State {
+			postings = append(postings, p)
+		}
+	}
+	return postings, nil
+}
+
+func (f *fakeRecruitmentRepo) FindPosting(ctx context.Context, orgID, id uuid.UUID) (*types.JobPosting, error) {
+	for _, p := range f.postings {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("job posting %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) FindPostingBySlug(ctx context.Context, orgID uuid.UUID, slug string) (*types.JobPosting, error) {
+	for _, p := range f.postings {
+		if p.OrganizationID == orgID && p.Slug == slug {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("job posting %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) FindOrganizationBySlug(ctx context.Context, slug string) (uuid.UUID, error) {
+	if id, ok := f.orgs[slug]; ok {
+		return id, nil
+	}
+	return uuid.Nil, fmt.Errorf("organization %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) CreatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error) {
+	posting.ID = uuid.New()
+	f.postings = append(f.postings, posting)
+	return &posting, nil
+}
+
+func (f *fakeRecruitmentRepo) UpdatePosting(ctx context.Context, userID *uuid.UUID, posting types.JobPosting) (*types.JobPosting, error) {
+	for i := range f.postings {
+		if f.postings[i].ID == posting.ID {
+			f.postings[i] = posting
+		}
+	}
+	return &posting, nil
+}
+
+func (f *fakeRecruitmentRepo) ListCandidates(ctx context.Context, filter types.CandidateFilter) ([]types.Candidate, error) {
+	return f.candidates, nil
+}
+
+func (f *fakeRecruitmentRepo) FindCandidate(ctx context.Context, orgID, id uuid.UUID) (*types.Candidate, error) {
+	for _, c := range f.candidates {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("candidate %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) FindCandidateByEmail(ctx context.Context, postingID uuid.UUID, email string) (*types.Candidate, error) {
+	for _, c := range f.candidates {
+		if c.JobPostingID == postingID && strings.EqualFold(c.Email, email) {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("candidate %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) CreateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate, documents []types.Document) (*types.Candidate, error) {
+	candidate.ID = uuid.New()
+	for _, d := range documents {
+		d.ID = uuid.New()
+		d.CandidateID = candidate.ID
+		f.documents = append(f.documents, d)
+	}
+	f.candidates = append(f.candidates, candidate)
+	return &candidate, nil
+}
+
+func (f *fakeRecruitmentRepo) UpdateCandidate(ctx context.Context, userID *uuid.UUID, candidate types.Candidate) (*types.Candidate, error) {
+	for i := range f.candidates {
+		if f.candidates[i].ID == candidate.ID {
+			f.candidates[i] = candidate
+		}
+	}
+	return &candidate, nil
+}
+
+func (f *fakeRecruitmentRepo) MoveCandidate(ctx context.Context, change types.StageChange, hired bool) (*types.StageChange, error) {
+	for i := range f.candidates {
+		if f.candidates[i].ID == change.CandidateID {
+			f.candidates[i].StageID = change.ToStageID
+			if hired {
+				f.candidates[i].Status = types.CandidateHired
+				f.candidates[i].HiredAt = &change.ChangedAt
+			}
+		}
+	}
+	f.moves = append(f.moves, change)
+	return &change, nil
+}
+
+func (f *fakeRecruitmentRepo) StageHistory(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.StageChange, error) {
+	return f.moves, nil
+}
+
+func (f *fakeRecruitmentRepo) AddDocument(ctx context.Context, document types.Document) (*types.Document, error) {
+	f.documents = append(f.documents, document)
+	return &document, nil
+}
+
+func (f *fakeRecruitmentRepo) DeleteDocument(ctx context.Context, orgID, candidateID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeRecruitmentRepo) ListActivities(ctx context.Context, orgID, candidateID uuid.UUID) ([]types.Activity, error) {
+	return nil, nil
+}
+
+func (f *fakeRecruitmentRepo) CreateActivity(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity) (*types.Activity, error) {
+	return &activity, nil
+}
+
+func (f *fakeRecruitmentRepo) ListInterviews(ctx context.Context, filter types.InterviewFilter) ([]types.Interview, error) {
+	var interviews []types.Interview
+	for _, i := range f.interviews {
+		if (filter.CandidateID == nil || i.CandidateID == *filter.CandidateID) && (filter.State == "" || i.State == filter.State) {
+			interviews = append(interviews, i)
+		}
+	}
+	return interviews, nil
+}
+
+func (f *fakeRecruitmentRepo) FindInterview(ctx context.Context, orgID, id uuid.UUID) (*types.Interview, error) {
+	for _, i := range f.interviews {
+		if i.ID == id {
+			return &i, nil
+		}
+	}
+	return nil, fmt.Errorf("interview %w", repository.ErrNotFound)
+}
+
+func (f *fakeRecruitmentRepo) CreateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error) {
+	interview.ID = uuid.New()
+	f.interviews = append(f.interviews, interview)
+	return &interview, nil
+}
+
+func (f *fakeRecruitmentRepo) UpdateInterview(ctx context.Context, userID *uuid.UUID, interview types.Interview) (*types.Interview, error) {
+	for i := range f.interviews {
+		if f.interviews[i].ID == interview.ID {
+			f.interviews[i] = interview
+		}
+	}
+	return &interview, nil
+}
+
+func (f *fakeRecruitmentRepo) BusyInterviews(ctx context.Context, orgID uuid.UUID, interviewerIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]types.Interview, error) {
+	var busy []types.Interview
+	for _, i := range f.interviews {
+		if i.State != types.InterviewScheduled || (excludeID != nil && i.ID == *excludeID) {
+			continue
+		}
+		if !i.StartsAt.Before(to) || !i.EndsAt.After(from) {
+			continue
+		}
+		for _, a := range i.InterviewerIDs {
+			for _, b := range interviewerIDs {
+				if a == b {
+					busy = append(busy, i)
+				}
+			}
+		}
+	}
+	return busy, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+// officeHours is open 09:00-17:00 on weekdays, with Friday 4 April 2025 off
+type officeHours struct{}
+
+func (officeHours) Resolve(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*calendar.Calendar, error) {
+	day := []calendar.Interval{{Start: 9 * 60, End: 17 * 60}}
+	return calendar.New(time.UTC, calendar.WeeklyHours{
+		time.Monday: day, time.Tuesday: day, time.Wednesday: day, time.Thursday: day, time.Friday: day,
+	}, []calendar.Holiday{{Date: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), Name: "Company day"}})
+}
+
+var (
+	orgID    = uuid.New()
+	postedAt = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+)
+
+// newFixture sets up a published posting on the default pipeline of
+// Applied, Interview and Hired on Monday 31 March 2025 at 18:00
+func newFixture(t *testing.T) (*RecruitmentService, *fakeRecruitmentRepo, *types.JobPosting) {
+	t.Helper()
+	recruiterID := uuid.New()
+	positionID := uuid.New()
+	posting := types.JobPosting{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		JobPositionID:  &positionID,
+		Title:          "Backend Engineer",
+		Slug:           "backend-engineer",
+		State:          types.PostingPublished,
+		RecruiterID:    &recruiterID,
+		PublishedAt:    &postedAt,
+	}
+	repo := &fakeRecruitmentRepo{
+		orgs: map[string]uuid.UUID{"acme": orgID},
+		stages: []types.Stage{
+			{ID: uuid.New(), OrganizationID: orgID, Name: "Applied", Sequence: 10},
+			{ID: uuid.New(), OrganizationID: orgID, Name: "Interview", Sequence: 20},
+			{ID: uuid.New(), OrganizationID: orgID, Name: "Hired", Sequence: 30, Hired: true},
+		},
+		postings: []types.JobPosting{posting},
+	}
+	svc := NewRecruitmentService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC) }
+	svc.SetBusinessCalendars(officeHours{})
+	return svc, repo, &posting
+}
+
+func apply(t *testing.T, svc *RecruitmentService, repo *fakeRecruitmentRepo) *types.Candidate {
+	t.Helper()
+	require.NoError(t, svc.Apply(context.Background(), "acme", "backend-engineer", types.Application{
+		Name:      "Ada Lovelace",
+		Email:     "ada@example.com",
+		ResumeURL: "https://files.example.com/ada.pdf",
+	}))
+	require.NotEmpty(t, repo.candidates)
+	return &repo.candidates[len(repo.candidates)-1]
+}
+
+func TestApplyCreatesCandidateInFirstStage(t *testing.T) {
+	svc, repo, posting := newFixture(t)
+
+	candidate := apply(t, svc, repo)
+	assert.Equal(t, repo.stages[0].ID, *candidate.StageID)
+	assert.Equal(t, posting.RecruiterID, candidate.RecruiterID)
+	assert.Equal(t, types.SourceCareersPage, candidate.Source)
+	assert.Equal(t, types.CandidateActive, candidate.Status)
+	require.Len(t, repo.documents, 1)
+	assert.Equal(t, types.DocumentResume, repo.documents[0].Kind)
+	assert.Equal(t, candidate.ID, repo.documents[0].CandidateID)
+}
+
+func TestApplyTwiceKeepsOneCandidate(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+
+	apply(t, svc, repo)
+	err := svc.Apply(context.Background(), "acme", "backend-engineer", types.Application{Name: "Ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+	assert.Len(t, repo.candidates, 1)
+}
+
+func TestApplyRejectsClosedAndInvalidApplications(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+	app := types.Application{Name: "Ada", Email: "ada@example.com"}
+
+	err := svc.Apply(context.Background(), "acme", "backend-engineer", types.Application{Name: "Ada", Email: "not an email"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	err = svc.Apply(context.Background(), "acme", "backend-engineer", types.Application{Name: "Ada", Email: "ada@example.com", ResumeURL: "javascript:alert(1)"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	err = svc.Apply(context.Background(), "unknown", "backend-engineer", app)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	repo.postings[0].State = types.PostingClosed
+	err = svc.Apply(context.Background(), "acme", "backend-engineer", app)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	postings, err := svc.CareersPostings(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Empty(t, postings)
+	assert.Empty(t, repo.candidates)
+}
+
+func TestMoveToHiredStageHiresCandidate(t *testing.T) {
+	svc, repo, posting := newFixture(t)
+	bus := events.NewBus(false)
+	svc.eventBus = bus
+	hired := make(chan types.HiredEvent, 1)
+	bus.Subscribe(EventCandidateHired, func(ctx context.Context, event events.Event) error {
+		hired <- event.Payload.(types.HiredEvent)
+		return nil
+	})
+	candidate := apply(t, svc, repo)
+
+	change, err := svc.MoveCandidate(context.Background(), orgID, uuid.New(), candidate.ID, types.MoveRequest{StageID: repo.stages[2].ID})
+	require.NoError(t, err)
+	assert.Equal(t, repo.stages[0].ID, *change.FromStageID)
+	assert.Equal(t, types.CandidateHired, repo.candidates[0].Status)
+
+	select {
+	case event := <-hired:
+		assert.Equal(t, candidate.ID, event.CandidateID)
+		assert.Equal(t, posting.JobPositionID, event.JobPositionID)
+		assert.Equal(t, "ada@example.com", event.Email)
+	case <-time.After(time.Second):
+		t.Fatal("candidate hired event not published")
+	}
+
+	_, err = svc.MoveCandidate(context.Background(), orgID, uuid.New(), candidate.ID, types.MoveRequest{StageID: repo.stages[1].ID})
+	assert.ErrorIs(t, err, repository.ErrInvalidState)
+}
+
+func TestMoveRejectsStageOfAnotherPipeline(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+	candidate := apply(t, svc, repo)
+	otherPosting := uuid.New()
+	other := types.Stage{ID: uuid.New(), OrganizationID: orgID, JobPostingID: &otherPosting, Name: "Screening"}
+	repo.stages = append(repo.stages, other)
+
+	_, err := svc.MoveCandidate(context.Background(), orgID, uuid.New(), candidate.ID, types.MoveRequest{StageID: other.ID})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = svc.MoveCandidate(context.Background(), orgID, uuid.New(), candidate.ID, types.MoveRequest{StageID: repo.stages[0].ID})
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Empty(t, repo.moves)
+}
+
+func TestScheduleInterviewWithinBusinessHours(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+	candidate := apply(t, svc, repo)
+	interviewer := uuid.New()
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 4, day, hour, minute, 0, 0, time.UTC) }
+	schedule := func(start, end time.Time) error {
+		_, err := svc.ScheduleInterview(context.Background(), orgID, uuid.New(), candidate.ID, types.InterviewRequest{
+			InterviewerIDs: []uuid.UUID{interviewer},
+			StartsAt:       start,
+			EndsAt:         end,
+		})
+		return err
+	}
+
+	assert.ErrorIs(t, schedule(at(1, 16, 30), at(1, 17, 30)), ErrInvalid, "runs past closing time")
+	assert.ErrorIs(t, schedule(at(5, 10, 0), at(5, 11, 0)), ErrInvalid, "saturday")
+	assert.ErrorIs(t, schedule(at(4, 10, 0), at(4, 11, 0)), ErrInvalid, "holiday")
+	assert.ErrorIs(t, schedule(at(1, 11, 0), at(1, 10, 0)), ErrInvalid, "ends before it starts")
+
+	require.NoError(t, schedule(at(1, 10, 0), at(1, 11, 0)))
+	assert.ErrorIs(t, schedule(at(1, 10, 30), at(1, 11, 30)), ErrInterviewConflict)
+	assert.NoError(t, schedule(at(1, 11, 0), at(1, 12, 0)), "back to back")
+	assert.Len(t, repo.interviews, 2)
+}
+
+func TestFreeSlotsSkipBookedInterviews(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+	interviewer := uuid.New()
+	day := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	repo.interviews = []types.Interview{{
+		ID:             uuid.New(),
+		InterviewerIDs: []uuid.UUID{interviewer},
+		StartsAt:       time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC),
+		EndsAt:         time.Date(2025, 4, 1, 15, 0, 0, 0, time.UTC),
+		State:          types.InterviewScheduled,
+	}}
+
+	slots, err := svc.FreeSlots(context.Background(), orgID, []uuid.UUID{interviewer}, day, time.Hour)
+	require.NoError(t, err)
+
+	var starts []string
+	for _, s := range slots {
+		starts = append(starts, s.StartsAt.Format("15:04"))
+	}
+	assert.Equal(t, []string{"09:00", "15:00", "15:15", "15:30", "15:45", "16:00"}, starts)
+
+	slots, err = svc.FreeSlots(context.Background(), orgID, []uuid.UUID{interviewer}, day.AddDate(0, 0, 3), time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, slots, "holiday")
+}
+
+func TestRefuseCancelsScheduledInterviews(t *testing.T) {
+	svc, repo, _ := newFixture(t)
+	candidate := apply(t, svc, repo)
+	interview, err := svc.ScheduleInterview(context.Background(), orgID, uuid.New(), candidate.ID, types.InterviewRequest{
+		InterviewerIDs: []uuid.UUID{uuid.New()},
+		StartsAt:       time.Date(2025, 4, 2, 14, 0, 0, 0, time.UTC),
+		EndsAt:         time.Date(2025, 4, 2, 15, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	refused, err := svc.RefuseCandidate(context.Background(), orgID, uuid.New(), candidate.ID, types.RefuseRequest{Withdrawn: true, Reason: "Accepted another offer"})
+	require.NoError(t, err)
+	assert.Equal(t, types.CandidateWithdrawn, refused.Status)
+
+	cancelled, err := repo.FindInterview(context.Background(), orgID, interview.ID)
+	require.NoError(t, err)
+	assert.Equal(t, types.InterviewCancelled, cancelled.State)
+}
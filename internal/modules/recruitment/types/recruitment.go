@@ -0,0 +1,351 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostingState is where a job posting stands
+type PostingState string
+
+const (
+	PostingDraft     PostingState = "draft"
+	PostingPublished PostingState = "published"
+	PostingClosed    PostingState = "closed"
+)
+
+func (s PostingState) IsValid() bool {
+	return s == PostingDraft || s == PostingPublished || s == PostingClosed
+}
+
+// CandidateStatus is whether a candidate is still in the running
+type CandidateStatus string
+
+const (
+	CandidateActive    CandidateStatus = "active"
+	CandidateHired     CandidateStatus = "hired"
+	CandidateRefused   CandidateStatus = "refused"
+	CandidateWithdrawn CandidateStatus = "withdrawn"
+)
+
+func (s CandidateStatus) IsValid() bool {
+	switch s {
+	case CandidateActive, CandidateHired, CandidateRefused, CandidateWithdrawn:
+		return true
+	}
+	return false
+}
+
+// SourceCareersPage marks candidates who applied through the careers page
+const SourceCareersPage = "careers_page"
+
+// DocumentKind is what a candidate document is
+type DocumentKind string
+
+const (
+	DocumentResume      DocumentKind = "resume"
+	DocumentCoverLetter DocumentKind = "cover_letter"
+	DocumentPortfolio   DocumentKind = "portfolio"
+	DocumentOther       DocumentKind = "other"
+)
+
+func (k DocumentKind) IsValid() bool {
+	switch k {
+	case DocumentResume, DocumentCoverLetter, DocumentPortfolio, DocumentOther:
+		return true
+	}
+	return false
+}
+
+// InterviewState is where an interview stands
+type InterviewState string
+
+const (
+	InterviewScheduled InterviewState = "scheduled"
+	InterviewCompleted InterviewState = "completed"
+	InterviewCancelled InterviewState = "cancelled"
+)
+
+func (s InterviewState) IsValid() bool {
+	return s == InterviewScheduled || s == InterviewCompleted || s == InterviewCancelled
+}
+
+// Stage is a column of a recruitment pipeline. Stages without a posting
+// are the organization default, used by postings without stages of their own.
+type Stage struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	JobPostingID   *uuid.UUID `json:"job_posting_id,omitempty"`
+	Name           string     `json:"name"`
+	Sequence       int        `json:"sequence"`
+	Fold           bool       `json:"fold"`
+	// Hired stages mark the candidates moved into them hired
+	Hired     bool      `json:"hired"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StageRequest creates or replaces a stage
+type StageRequest struct {
+	JobPostingID *uuid.UUID `json:"job_posting_id,omitempty"`
+	Name         string     `json:"name"`
+	Sequence     int        `json:"sequence"`
+	Fold         bool       `json:"fold"`
+	Hired        bool       `json:"hired"`
+}
+
+// JobPosting is an advertised opening
+type JobPosting struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	JobPositionID  *uuid.UUID   `json:"job_position_id,omitempty"`
+	DepartmentID   *uuid.UUID   `json:"department_id,omitempty"`
+	Title          string       `json:"title"`
+	Slug           string       `json:"slug"`
+	Description    string       `json:"description,omitempty"`
+	Requirements   string       `json:"requirements,omitempty"`
+	Location       string       `json:"location,omitempty"`
+	EmploymentType string       `json:"employment_type,omitempty"`
+	State          PostingState `json:"state"`
+	RecruiterID    *uuid.UUID   `json:"recruiter_id,omitempty"`
+	PublishedAt    *time.Time   `json:"published_at,omitempty"`
+	ClosesAt       *time.Time   `json:"closes_at,omitempty"`
+	// Candidates counts active candidates
+	Candidates int       `json:"candidates"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsOpen reports whether the posting accepts applications at t
+func (p JobPosting) IsOpen(t time.Time) bool {
+	return p.State == PostingPublished && (p.ClosesAt == nil || t.Before(*p.ClosesAt))
+}
+
+// JobPostingRequest creates or replaces a posting. The slug defaults to
+// one derived from the title.
+type JobPostingRequest struct {
+	JobPositionID  *uuid.UUID `json:"job_position_id,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	Title          string     `json:"title"`
+	Slug           string     `json:"slug,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Requirements   string     `json:"requirements,omitempty"`
+	Location       string     `json:"location,omitempty"`
+	EmploymentType string     `json:"employment_type,omitempty"`
+	RecruiterID    *uuid.UUID `json:"recruiter_id,omitempty"`
+	ClosesAt       *time.Time `json:"closes_at,omitempty"`
+}
+
+// PostingFilter selects job postings
+type PostingFilter struct {
+	OrganizationID uuid.UUID
+	State          PostingState
+	DepartmentID   *uuid.UUID
+}
+
+// PublicPosting is a posting as shown on the careers page
+type PublicPosting struct {
+	Title          string     `json:"title"`
+	Slug           string     `json:"slug"`
+	Description    string     `json:"description,omitempty"`
+	Requirements   string     `json:"requirements,omitempty"`
+	Location       string     `json:"location,omitempty"`
+	EmploymentType string     `json:"employment_type,omitempty"`
+	PublishedAt    *time.Time `json:"published_at,omitempty"`
+	ClosesAt       *time.Time `json:"closes_at,omitempty"`
+}
+
+// Document is a file attached to a candidate
+type Document struct {
+	ID             uuid.UUID    `json:"id"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	CandidateID    uuid.UUID    `json:"candidate_id"`
+	Kind           DocumentKind `json:"kind"`
+	Name           string       `json:"name"`
+	URL            string       `json:"url"`
+	UploadedBy     *uuid.UUID   `json:"uploaded_by,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// DocumentRequest attaches a document to a candidate
+type DocumentRequest struct {
+	Kind DocumentKind `json:"kind"`
+	Name string       `json:"name"`
+	URL  string       `json:"url"`
+}
+
+// Candidate is a person applying to a posting
+type Candidate struct {
+	ID                  uuid.UUID       `json:"id"`
+	OrganizationID      uuid.UUID       `json:"organization_id"`
+	JobPostingID        uuid.UUID       `json:"job_posting_id"`
+	StageID             *uuid.UUID      `json:"stage_id,omitempty"`
+	Name                string          `json:"name"`
+	Email               string          `json:"email"`
+	Phone               string          `json:"phone,omitempty"`
+	Source              string          `json:"source"`
+	RecruiterID         *uuid.UUID      `json:"recruiter_id,omitempty"`
+	Rating              int             `json:"rating"`
+	Status              CandidateStatus `json:"status"`
+	RefuseReason        string          `json:"refuse_reason,omitempty"`
+	CoverLetter         string          `json:"cover_letter,omitempty"`
+	AppliedAt           time.Time       `json:"applied_at"`
+	DateLastStageUpdate *time.Time      `json:"date_last_stage_update,omitempty"`
+	HiredAt             *time.Time      `json:"hired_at,omitempty"`
+	Documents           []Document      `json:"documents,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// CandidateRequest adds a candidate by hand or updates one. The stage
+// defaults to the first stage of the posting's pipeline and only applies
+// on creation; candidates change stage through a move.
+type CandidateRequest struct {
+	JobPostingID uuid.UUID  `json:"job_posting_id"`
+	StageID      *uuid.UUID `json:"stage_id,omitempty"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	RecruiterID  *uuid.UUID `json:"recruiter_id,omitempty"`
+	Rating       int        `json:"rating"`
+	CoverLetter  string     `json:"cover_letter,omitempty"`
+}
+
+// CandidateFilter selects candidates
+type CandidateFilter struct {
+	OrganizationID uuid.UUID
+	JobPostingID   *uuid.UUID
+	StageID        *uuid.UUID
+	RecruiterID    *uuid.UUID
+	Status         CandidateStatus
+	Search         string
+	Limit          int
+	Offset         int
+}
+
+// Application is a careers page application
+type Application struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone,omitempty"`
+	CoverLetter  string `json:"cover_letter,omitempty"`
+	ResumeURL    string `json:"resume_url,omitempty"`
+	PortfolioURL string `json:"portfolio_url,omitempty"`
+}
+
+// MoveRequest moves a candidate to another stage
+type MoveRequest struct {
+	StageID uuid.UUID `json:"stage_id"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// StageChange records a candidate moving from one stage to another
+type StageChange struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CandidateID    uuid.UUID  `json:"candidate_id"`
+	FromStageID    *uuid.UUID `json:"from_stage_id,omitempty"`
+	ToStageID      *uuid.UUID `json:"to_stage_id,omitempty"`
+	ChangedBy      *uuid.UUID `json:"changed_by,omitempty"`
+	ChangedAt      time.Time  `json:"changed_at"`
+	// DurationSeconds is how long the candidate spent in the from stage
+	DurationSeconds int64  `json:"duration_seconds"`
+	Note            string `json:"note,omitempty"`
+}
+
+// RefuseRequest refuses a candidate, or records that they withdrew
+type RefuseRequest struct {
+	Withdrawn bool   `json:"withdrawn,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Activity is a call, meeting, email, to-do or note scheduled on a
+// candidate. Activities are shared with the CRM and point at the
+// candidate through their record reference.
+type Activity struct {
+	ID           uuid.UUID  `json:"id"`
+	CandidateID  uuid.UUID  `json:"candidate_id"`
+	ActivityType string     `json:"activity_type"`
+	Summary      string     `json:"summary"`
+	Note         string     `json:"note,omitempty"`
+	DateDeadline *time.Time `json:"date_deadline,omitempty"`
+	AssignedTo   *uuid.UUID `json:"assigned_to,omitempty"`
+	State        string     `json:"state"`
+	DoneDate     *time.Time `json:"done_date,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ActivityRequest schedules an activity on a candidate
+type ActivityRequest struct {
+	ActivityType string     `json:"activity_type"`
+	Summary      string     `json:"summary"`
+	Note         string     `json:"note,omitempty"`
+	DateDeadline *time.Time `json:"date_deadline,omitempty"`
+	AssignedTo   *uuid.UUID `json:"assigned_to,omitempty"`
+}
+
+// Interview is an interview of a candidate
+type Interview struct {
+	ID             uuid.UUID      `json:"id"`
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	CandidateID    uuid.UUID      `json:"candidate_id"`
+	Title          string         `json:"title"`
+	InterviewerIDs []uuid.UUID    `json:"interviewer_ids"`
+	StartsAt       time.Time      `json:"starts_at"`
+	EndsAt         time.Time      `json:"ends_at"`
+	Location       string         `json:"location,omitempty"`
+	MeetingURL     string         `json:"meeting_url,omitempty"`
+	State          InterviewState `json:"state"`
+	Feedback       string         `json:"feedback,omitempty"`
+	Rating         *int           `json:"rating,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// InterviewRequest schedules or reschedules an interview
+type InterviewRequest struct {
+	Title          string      `json:"title"`
+	InterviewerIDs []uuid.UUID `json:"interviewer_ids"`
+	StartsAt       time.Time   `json:"starts_at"`
+	EndsAt         time.Time   `json:"ends_at"`
+	Location       string      `json:"location,omitempty"`
+	MeetingURL     string      `json:"meeting_url,omitempty"`
+}
+
+// InterviewFeedback completes an interview
+type InterviewFeedback struct {
+	Feedback string `json:"feedback"`
+	Rating   *int   `json:"rating,omitempty"`
+}
+
+// InterviewFilter selects interviews
+type InterviewFilter struct {
+	OrganizationID uuid.UUID
+	CandidateID    *uuid.UUID
+	InterviewerID  *uuid.UUID
+	State          InterviewState
+	From           *time.Time
+	To             *time.Time
+}
+
+// Slot is a free interview slot
+type Slot struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// HiredEvent is the payload published when a candidate is hired, for HR to
+// create the employee record
+type HiredEvent struct {
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CandidateID    uuid.UUID  `json:"candidate_id"`
+	JobPostingID   uuid.UUID  `json:"job_posting_id"`
+	JobPositionID  *uuid.UUID `json:"job_position_id,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	Phone          string     `json:"phone,omitempty"`
+	HiredAt        time.Time  `json:"hired_at"`
+}
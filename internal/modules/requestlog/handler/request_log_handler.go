@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RequestLogHandler handles the request log console of platform
+// administrators
+type RequestLogHandler struct {
+	service *service.RequestLogService
+}
+
+func NewRequestLogHandler(service *service.RequestLogService) *RequestLogHandler {
+	return &RequestLogHandler{service: service}
+}
+
+func (h *RequestLogHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/admin/request-logs", h.Search)
+	router.GET("/api/v1/admin/request-logs/:id", h.GetEntry)
+	router.POST("/api/v1/admin/request-logs/:id/replay", h.Replay)
+	router.GET("/api/v1/admin/request-logs/:id/replays", h.ListReplays)
+
+	router.GET("/api/v1/admin/request-log-settings/:orgId", h.GetSettings)
+	router.PUT("/api/v1/admin/request-log-settings/:orgId", h.UpdateSettings)
+}
+
+// Search handles GET /api/v1/admin/request-logs, filtered by
+// organization_id, route (ending in * for a prefix), method, status,
+// min_status, max_status, from and to (RFC 3339), with limit and offset
+func (h *RequestLogHandler) Search(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.Filter{Route: query.Get("route"), Method: query.Get("method")}
+	if v := query.Get("organization_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+		filter.OrganizationID = &id
+	}
+	for name, dest := range map[string]*int{
+		"status":     &filter.Status,
+		"min_status": &filter.MinStatus,
+		"max_status": &filter.MaxStatus,
+		"limit":      &filter.Limit,
+		"offset":     &filter.Offset,
+	} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = n
+		}
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	entries, err := h.service.Search(r.Context(), filter)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   entries,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetEntry handles GET /api/v1/admin/request-logs/:id, a logged request
+// with its captured bodies
+func (h *RequestLogHandler) GetEntry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid request log ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.GetEntry(r.Context(), id)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// Replay handles POST /api/v1/admin/request-logs/:id/replay, sending a
+// failed request again in its organization's sandbox
+func (h *RequestLogHandler) Replay(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid request log ID", http.StatusBadRequest)
+		return
+	}
+
+	replay, err := h.service.Replay(r.Context(), id)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, replay)
+}
+
+// ListReplays handles GET /api/v1/admin/request-logs/:id/replays
+func (h *RequestLogHandler) ListReplays(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid request log ID", http.StatusBadRequest)
+		return
+	}
+
+	replays, err := h.service.ListReplays(r.Context(), id)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, replays)
+}
+
+// GetSettings handles GET /api/v1/admin/request-log-settings/:orgId
+func (h *RequestLogHandler) GetSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	orgID, err := uuid.Parse(ps.ByName("orgId"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), orgID)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/v1/admin/request-log-settings/:orgId
+func (h *RequestLogHandler) UpdateSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+
+	orgID, err := uuid.Parse(ps.ByName("orgId"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), orgID, req)
+	if err != nil {
+		writeRequestLogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeRequestLogError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotReplayable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrReplayUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/service"
+)
+
+// finalFlushTimeout bounds saving the queued requests on shutdown
+const finalFlushTimeout = 5 * time.Second
+
+// LogRunner saves queued requests in the background and deletes those past
+// their retention. Every instance runs one for its own queue.
+type LogRunner struct {
+	requestLogService *service.RequestLogService
+	logger            *slog.Logger
+}
+
+func NewLogRunner(requestLogService *service.RequestLogService, logger *slog.Logger) *LogRunner {
+	return &LogRunner{
+		requestLogService: requestLogService,
+		logger:            logger,
+	}
+}
+
+// Start saves queued requests every flush interval, and purges old ones
+// every purge interval, until ctx is cancelled
+func (r *LogRunner) Start(ctx context.Context) {
+	go func() {
+		flush := time.NewTicker(service.FlushInterval)
+		defer flush.Stop()
+		purge := time.NewTicker(service.PurgeInterval)
+		defer purge.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+				r.flush(flushCtx)
+				cancel()
+				return
+			case <-flush.C:
+				r.flush(ctx)
+			case <-purge.C:
+				r.purge(ctx)
+			}
+		}
+	}()
+}
+
+func (r *LogRunner) flush(ctx context.Context) {
+	if _, err := r.requestLogService.Flush(ctx); err != nil {
+		r.logger.Error("Request log flush failed", "error", err)
+	}
+}
+
+func (r *LogRunner) purge(ctx context.Context) {
+	purged, err := r.requestLogService.Purge(ctx)
+	if err != nil {
+		r.logger.Error("Request log purge failed", "error", err)
+	}
+	if purged > 0 {
+		r.logger.Info("Request logs purged", "count", purged)
+	}
+}
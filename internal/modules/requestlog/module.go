@@ -0,0 +1,90 @@
+package requestlog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/service"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RequestLogModule represents the API request log module
+type RequestLogModule struct {
+	requestLogService *service.RequestLogService
+	requestLogHandler *handler.RequestLogHandler
+	logRunner         *jobs.LogRunner
+	logger            *slog.Logger
+}
+
+// NewRequestLogModule creates a new request log module
+func NewRequestLogModule() *RequestLogModule {
+	return &RequestLogModule{}
+}
+
+// Name returns the module name
+func (m *RequestLogModule) Name() string {
+	return "requestlog"
+}
+
+// Init initializes the request log module and starts saving logged requests
+func (m *RequestLogModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "requestlog")
+	m.logger.Info("Initializing request log module")
+
+	// Create repositories
+	requestLogRepo := repository.NewRequestLogRepository(deps.DB)
+
+	// Create services
+	m.requestLogService = service.NewRequestLogService(requestLogRepo, m.logger)
+
+	// Save logged requests in the background
+	m.logRunner = jobs.NewLogRunner(m.requestLogService, m.logger)
+	m.logRunner.Start(ctx)
+
+	// Create handlers
+	m.requestLogHandler = handler.NewRequestLogHandler(m.requestLogService)
+
+	m.logger.Info("Request log module initialized successfully")
+	return nil
+}
+
+// Middleware logs the requests passing through next; before Init it
+// returns next unchanged
+func (m *RequestLogModule) Middleware(next http.Handler) http.Handler {
+	if m.requestLogService == nil {
+		return next
+	}
+	return m.requestLogService.Middleware(next)
+}
+
+// SetReplayHandler sets the handler failed requests are replayed with
+func (m *RequestLogModule) SetReplayHandler(h http.Handler) {
+	if m.requestLogService != nil {
+		m.requestLogService.SetReplayHandler(h)
+	}
+}
+
+// RegisterRoutes registers request log module routes
+func (m *RequestLogModule) RegisterRoutes(router interface{}) {
+	if m.requestLogHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.requestLogHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the request log module
+func (m *RequestLogModule) RegisterEventHandlers(bus interface{}) {
+	// The request log records HTTP traffic; it does not handle events
+}
+
+// Health checks the health of the request log module
+func (m *RequestLogModule) Health() error {
+	return nil
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a logged request or an organization's
+// sandbox does not exist
+var ErrNotFound = errors.New("not found")
+
+// RequestLogRepo defines the interface for request log repository operations
+type RequestLogRepo interface {
+	// GetSettings returns the organization's settings, nil when it has none
+	GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error)
+	SaveSettings(ctx context.Context, settings types.Settings) (*types.Settings, error)
+
+	// InsertEntries saves logged requests in one statement
+	InsertEntries(ctx context.Context, entries []types.Entry) error
+	// Search returns the matching requests, newest first, without bodies
+	Search(ctx context.Context, filter types.Filter) ([]types.Entry, error)
+	GetEntry(ctx context.Context, id uuid.UUID) (*types.Entry, error)
+	// Purge deletes the requests logged before cutoff
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// SandboxFor returns the sandbox organization paired with orgID
+	SandboxFor(ctx context.Context, orgID uuid.UUID) (uuid.UUID, error)
+	CreateReplay(ctx context.Context, replay types.Replay) (*types.Replay, error)
+	ListReplays(ctx context.Context, requestLogID uuid.UUID) ([]types.Replay, error)
+}
+
+// RequestLogRepository stores logged requests, capture settings and replays
+type RequestLogRepository struct {
+	db *sql.DB
+}
+
+// Ensure RequestLogRepository implements RequestLogRepo interface
+var _ RequestLogRepo = &RequestLogRepository{}
+
+func NewRequestLogRepository(db *sql.DB) *RequestLogRepository {
+	return &RequestLogRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const settingsColumns = `organization_id, enabled, sample_rate::float8, capture_request_body, capture_response_body,
+	max_body_bytes, redact_fields, updated_by, updated_at`
+
+func scanSettings(row rowScanner) (*types.Settings, error) {
+	var s types.Settings
+	var redactFields pq.StringArray
+	err := row.Scan(&s.OrganizationID, &s.Enabled, &s.SampleRate, &s.CaptureRequestBody, &s.CaptureResponseBody,
+		&s.MaxBodyBytes, &redactFields, &s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.RedactFields = []string(redactFields)
+	return &s, nil
+}
+
+// GetSettings returns the organization's settings, nil when it has none
+func (r *RequestLogRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	query := `SELECT ` + settingsColumns + ` FROM request_log_settings WHERE organization_id = $1`
+
+	settings, err := scanSettings(r.db.QueryRowContext(ctx, query, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request log settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings inserts or replaces the organization's settings
+func (r *RequestLogRepository) SaveSettings(ctx context.Context, s types.Settings) (*types.Settings, error) {
+	query := `
+		INSERT INTO request_log_settings (
+			organization_id, enabled, sample_rate, capture_request_body, capture_response_body,
+			max_body_bytes, redact_fields, updated_by, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now())
+		ON CONFLICT (organization_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			sample_rate = EXCLUDED.sample_rate,
+			capture_request_body = EXCLUDED.capture_request_body,
+			capture_response_body = EXCLUDED.capture_response_body,
+			max_body_bytes = EXCLUDED.max_body_bytes,
+			redact_fields = EXCLUDED.redact_fields,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = now()
+		RETURNING ` + settingsColumns
+
+	settings, err := scanSettings(r.db.QueryRowContext(ctx, query,
+		s.OrganizationID, s.Enabled, s.SampleRate, s.CaptureRequestBody, s.CaptureResponseBody,
+		s.MaxBodyBytes, pq.Array(s.RedactFields), s.UpdatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save request log settings: %w", err)
+	}
+	return settings, nil
+}
+
+const entryColumns = `id, organization_id, user_id, roles, is_sandbox, method, route, path, query, status,
+	duration_ms, COALESCE(client_ip, ''), COALESCE(user_agent, ''), request_headers, request_size,
+	request_body, request_body_truncated, response_body, response_body_truncated, sampled, created_at`
+
+// entryListColumns are entryColumns without the bodies
+const entryListColumns = `id, organization_id, user_id, roles, is_sandbox, method, route, path, query, status,
+	duration_ms, COALESCE(client_ip, ''), COALESCE(user_agent, ''), request_headers, request_size,
+	NULL::text, request_body_truncated, NULL::text, response_body_truncated, sampled, created_at`
+
+func scanEntry(row rowScanner) (*types.Entry, error) {
+	var e types.Entry
+	var roles pq.StringArray
+	var headers []byte
+	var requestBody, responseBody sql.NullString
+	err := row.Scan(&e.ID, &e.OrganizationID, &e.UserID, &roles, &e.IsSandbox, &e.Method, &e.Route, &e.Path, &e.Query, &e.Status,
+		&e.DurationMs, &e.ClientIP, &e.UserAgent, &headers, &e.RequestSize,
+		&requestBody, &e.RequestBodyTruncated, &responseBody, &e.ResponseBodyTruncated, &e.Sampled, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Roles = []string(roles)
+	if err := json.Unmarshal(headers, &e.RequestHeaders); err != nil {
+		return nil, fmt.Errorf("failed to decode request headers: %w", err)
+	}
+	if requestBody.Valid {
+		e.RequestBody = &requestBody.String
+	}
+	if responseBody.Valid {
+		e.ResponseBody = &responseBody.String
+	}
+	return &e, nil
+}
+
+// InsertEntries saves logged requests in one statement
+func (r *RequestLogRepository) InsertEntries(ctx context.Context, entries []types.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	const columns = 21
+	var query strings.Builder
+	query.WriteString(`INSERT INTO request_logs (
+		id, organization_id, user_id, roles, is_sandbox, method, route, path, query, status,
+		duration_ms, client_ip, user_agent, request_headers, request_size,
+		request_body, request_body_truncated, response_body, response_body_truncated, sampled, created_at
+	) VALUES `)
+	args := make([]interface{}, 0, len(entries)*columns)
+	for i, e := range entries {
+		headers, err := json.Marshal(e.RequestHeaders)
+		if err != nil {
+			return fmt.Errorf("failed to encode request headers: %w", err)
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for c := 1; c <= columns; c++ {
+			if c > 1 {
+				query.WriteString(", ")
+			}
+			switch c {
+			case 12, 13:
+				fmt.Fprintf(&query, "NULLIF($%d, '')", i*columns+c)
+			default:
+				fmt.Fprintf(&query, "$%d", i*columns+c)
+			}
+		}
+		query.WriteString(")")
+		args = append(args, e.ID, e.OrganizationID, e.UserID, pq.Array(e.Roles), e.IsSandbox, e.Method, e.Route, e.Path, e.Query, e.Status,
+			e.DurationMs, e.ClientIP, e.UserAgent, headers, e.RequestSize,
+			e.RequestBody, e.RequestBodyTruncated, e.ResponseBody, e.ResponseBodyTruncated, e.Sampled, e.CreatedAt)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query.String(), args...); err != nil {
+		return fmt.Errorf("failed to insert request logs: %w", err)
+	}
+	return nil
+}
+
+// Search returns the matching requests, newest first, without bodies
+func (r *RequestLogRepository) Search(ctx context.Context, filter types.Filter) ([]types.Entry, error) {
+	query := `SELECT ` + entryListColumns + ` FROM request_logs WHERE true`
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.OrganizationID != nil {
+		query += ` AND organization_id = ` + arg(*filter.OrganizationID)
+	}
+	if filter.Route != "" {
+		if prefix, ok := strings.CutSuffix(filter.Route, "*"); ok {
+			query += ` AND starts_with(route, ` + arg(prefix) + `)`
+		} else {
+			query += ` AND route = ` + arg(filter.Route)
+		}
+	}
+	if filter.Method != "" {
+		query += ` AND method = ` + arg(filter.Method)
+	}
+	if filter.Status != 0 {
+		query += ` AND status = ` + arg(filter.Status)
+	}
+	if filter.MinStatus != 0 {
+		query += ` AND status >= ` + arg(filter.MinStatus)
+	}
+	if filter.MaxStatus != 0 {
+		query += ` AND status <= ` + arg(filter.MaxStatus)
+	}
+	if filter.From != nil {
+		query += ` AND created_at >= ` + arg(*filter.From)
+	}
+	if filter.To != nil {
+		query += ` AND created_at < ` + arg(*filter.To)
+	}
+	query += ` ORDER BY created_at DESC, id LIMIT ` + arg(filter.Limit) + ` OFFSET ` + arg(filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search request logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request log: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetEntry returns a logged request with its bodies
+func (r *RequestLogRepository) GetEntry(ctx context.Context, id uuid.UUID) (*types.Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM request_logs WHERE id = $1`
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("request log %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request log: %w", err)
+	}
+	return entry, nil
+}
+
+// Purge deletes the requests logged before cutoff, with their replays
+func (r *RequestLogRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM request_logs WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge request logs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// SandboxFor returns the sandbox organization paired with orgID
+func (r *RequestLogRepository) SandboxFor(ctx context.Context, orgID uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT id FROM organizations
+		WHERE sandbox_parent_id = $1 AND is_sandbox = true AND deleted_at IS NULL`
+
+	var sandboxID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, orgID).Scan(&sandboxID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("sandbox %w", ErrNotFound)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find sandbox: %w", err)
+	}
+	return sandboxID, nil
+}
+
+const replayColumns = `id, request_log_id, organization_id, replayed_by, status, duration_ms,
+	response_headers, response_body, response_body_truncated, created_at`
+
+func scanReplay(row rowScanner) (*types.Replay, error) {
+	var rp types.Replay
+	var headers []byte
+	var responseBody sql.NullString
+	err := row.Scan(&rp.ID, &rp.RequestLogID, &rp.OrganizationID, &rp.ReplayedBy, &rp.Status, &rp.DurationMs,
+		&headers, &responseBody, &rp.ResponseBodyTruncated, &rp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(headers, &rp.ResponseHeaders); err != nil {
+		return nil, fmt.Errorf("failed to decode response headers: %w", err)
+	}
+	if responseBody.Valid {
+		rp.ResponseBody = &responseBody.String
+	}
+	return &rp, nil
+}
+
+// CreateReplay records a replay
+func (r *RequestLogRepository) CreateReplay(ctx context.Context, rp types.Replay) (*types.Replay, error) {
+	headers, err := json.Marshal(rp.ResponseHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response headers: %w", err)
+	}
+
+	query := `
+		INSERT INTO request_log_replays (
+			id, request_log_id, organization_id, replayed_by, status, duration_ms,
+			response_headers, response_body, response_body_truncated, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+		RETURNING ` + replayColumns
+
+	replay, err := scanReplay(r.db.QueryRowContext(ctx, query,
+		rp.ID, rp.RequestLogID, rp.OrganizationID, rp.ReplayedBy, rp.Status, rp.DurationMs,
+		headers, rp.ResponseBody, rp.ResponseBodyTruncated))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request replay: %w", err)
+	}
+	return replay, nil
+}
+
+// ListReplays returns a logged request's replays, newest first
+func (r *RequestLogRepository) ListReplays(ctx context.Context, requestLogID uuid.UUID) ([]types.Replay, error) {
+	query := `SELECT ` + replayColumns + ` FROM request_log_replays
+		WHERE request_log_id = $1
+		ORDER BY created_at DESC, id`
+
+	rows, err := r.db.QueryContext(ctx, query, requestLogID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list request replays: %w", err)
+	}
+	defer rows.Close()
+
+	replays := []types.Replay{}
+	for rows.Next() {
+		replay, err := scanReplay(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request replay: %w", err)
+		}
+		replays = append(replays, *replay)
+	}
+	return replays, rows.Err()
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+// unloggedPaths are never logged
+var unloggedPaths = map[string]bool{
+	"/":       true,
+	"/health": true,
+}
+
+// adminPathPrefix covers the request log's own routes, which are not logged
+const adminPathPrefix = "/api/v1/admin/request-log"
+
+// settingsFor returns the organization's settings, the defaults when it
+// has none or they cannot be read
+func (s *RequestLogService) settingsFor(ctx context.Context, orgID uuid.UUID) types.Settings {
+	if orgID == uuid.Nil {
+		return DefaultSettings(orgID)
+	}
+	entry, err := s.settings.Get(ctx, orgID.String(), false, func(ctx context.Context) (types.Settings, error) {
+		return s.loadSettings(ctx, orgID)
+	})
+	if err != nil {
+		s.logger.Warn("Failed to load request log settings, using defaults", "organization_id", orgID, "error", err)
+		return DefaultSettings(orgID)
+	}
+	return entry.Value
+}
+
+func maxBodyBytes(settings types.Settings) int {
+	if settings.MaxBodyBytes > MaxBodyBytes {
+		return MaxBodyBytes
+	}
+	return settings.MaxBodyBytes
+}
+
+// Middleware logs every failed request and a sample of the others, with
+// their bodies as the organization's settings allow. It must run inside
+// the auth middleware so requests are attributed to their user; requests
+// the auth middleware rejects are not logged.
+func (s *RequestLogService) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || unloggedPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		authCtx, _ := auth.FromContext(r.Context())
+		var orgID, userID uuid.UUID
+		if authCtx != nil {
+			orgID, userID = authCtx.OrganizationID, authCtx.UserID
+		}
+		settings := s.settingsFor(r.Context(), orgID)
+		if !settings.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit := maxBodyBytes(settings)
+		started := s.now()
+
+		// The body is read up front, as much of it as is kept, and handed
+		// on whole
+		var requestBody []byte
+		captureRequest := settings.CaptureRequestBody && r.Body != nil && r.Body != http.NoBody && textual(r.Header.Get("Content-Type"))
+		if captureRequest {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+
+		rec := newResponseRecorder(w, limit, settings.CaptureResponseBody)
+		next.ServeHTTP(rec, r)
+
+		status := rec.statusCode()
+		sampled := status < http.StatusBadRequest
+		if sampled && s.sample() >= settings.SampleRate {
+			return
+		}
+
+		entry := types.Entry{
+			ID:             uuid.New(),
+			Method:         r.Method,
+			Route:          normalizeRoute(r.URL.Path),
+			Path:           escapedPath(r.URL),
+			Query:          r.URL.RawQuery,
+			Status:         status,
+			DurationMs:     s.now().Sub(started).Milliseconds(),
+			ClientIP:       clientIP(r),
+			UserAgent:      truncate(r.UserAgent(), 500),
+			RequestHeaders: redactHeaders(r.Header),
+			RequestSize:    r.ContentLength,
+			Sampled:        sampled,
+			CreatedAt:      started,
+		}
+		if authCtx != nil {
+			entry.Roles, entry.IsSandbox = authCtx.Roles, authCtx.IsSandbox
+		}
+		if orgID != uuid.Nil {
+			entry.OrganizationID = &orgID
+		}
+		if userID != uuid.Nil {
+			entry.UserID = &userID
+		}
+
+		redactor := newRedactor(settings.RedactFields)
+		if captureRequest {
+			entry.RequestBodyTruncated = len(requestBody) > limit
+			if entry.RequestBodyTruncated {
+				requestBody = requestBody[:limit]
+			}
+			body := redactor.redact(r.Header.Get("Content-Type"), validText(requestBody))
+			entry.RequestBody = &body
+		}
+		entry.ResponseBody, entry.ResponseBodyTruncated = rec.captured(redactor)
+
+		s.enqueue(entry)
+	})
+}
+
+// enqueue queues entry to be saved, dropping it when the queue is full so
+// that logging never slows requests down
+func (s *RequestLogService) enqueue(entry types.Entry) {
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Flush saves the queued requests, returning how many were saved
+func (s *RequestLogService) Flush(ctx context.Context) (int, error) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		s.logger.Warn("Request log queue full, requests dropped", "count", dropped)
+	}
+
+	saved := 0
+	for {
+		batch := make([]types.Entry, 0, flushBatchSize)
+	collect:
+		for len(batch) < flushBatchSize {
+			select {
+			case entry := <-s.queue:
+				batch = append(batch, entry)
+			default:
+				break collect
+			}
+		}
+		if len(batch) == 0 {
+			return saved, nil
+		}
+		if err := s.repo.InsertEntries(ctx, batch); err != nil {
+			return saved, err
+		}
+		saved += len(batch)
+	}
+}
+
+// responseRecorder passes a response through, noting its status and
+// keeping the start of textual bodies
+type responseRecorder struct {
+	http.ResponseWriter
+	header    http.Header
+	status    int
+	capture   bool
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+// newResponseRecorder records the response written to w, or only records
+// it when w is nil
+func newResponseRecorder(w http.ResponseWriter, limit int, capture bool) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, header: http.Header{}, limit: limit, capture: capture}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	if r.ResponseWriter == nil {
+		return r.header
+	}
+	return r.ResponseWriter.Header()
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = status
+	r.capture = r.capture && textual(r.Header().Get("Content-Type"))
+	if r.ResponseWriter != nil {
+		r.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		if r.Header().Get("Content-Type") == "" {
+			r.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.capture {
+		if room := r.limit - r.body.Len(); room < len(p) {
+			r.body.Write(p[:max(room, 0)])
+			r.truncated = true
+		} else {
+			r.body.Write(p)
+		}
+	}
+	if r.ResponseWriter == nil {
+		return len(p), nil
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush passes through to streaming responses
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// captured returns the redacted body kept, nil when it was not captured
+func (r *responseRecorder) captured(redactor *redactor) (*string, bool) {
+	if !r.capture || r.status == 0 {
+		return nil, false
+	}
+	body := redactor.redact(r.Header().Get("Content-Type"), validText(r.body.Bytes()))
+	return &body, r.truncated
+}
+
+// validText returns b as text, dropping a rune cut off by truncation and
+// replacing invalid bytes, which text columns reject
+func validText(b []byte) string {
+	return strings.ToValidUTF8(string(trimPartialRune(b)), "�")
+}
+
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return string(trimPartialRune([]byte(s[:n])))
+}
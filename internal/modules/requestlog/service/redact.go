@@ -0,0 +1,113 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the values of secret headers and body fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the body fields redacted when an organization has
+// not chosen its own
+var DefaultRedactFields = []string{
+	"password", "secret", "token", "access_token", "refresh_token",
+	"api_key", "client_secret", "card_number", "cvv", "iban",
+}
+
+// secretHeaders are never stored, whatever the settings
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// redactHeaders flattens headers, replacing secret ones
+func redactHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for name, values := range header {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			flat[name] = Redacted
+			continue
+		}
+		flat[name] = strings.Join(values, ", ")
+	}
+	return flat
+}
+
+// redactor replaces the values of the configured fields in captured bodies
+type redactor struct {
+	fields map[string]bool
+	json   *regexp.Regexp
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{fields: make(map[string]bool, len(fields))}
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || r.fields[field] {
+			continue
+		}
+		r.fields[field] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		sort.Strings(quoted)
+		// Only scalar values are replaced; the fields of an object under a
+		// redacted name are redacted by their own names
+		r.json = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|-?[0-9][0-9.eE+-]*|true|false|null)`)
+	}
+	return r
+}
+
+// redact returns body with the configured fields' values replaced. JSON is
+// rewritten textually so malformed bodies, which are often why a request
+// failed, are kept as sent.
+func (r *redactor) redact(contentType, body string) string {
+	if r.json == nil || body == "" {
+		return body
+	}
+	switch {
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		return r.redactForm(body)
+	case strings.Contains(contentType, "json"):
+		return r.json.ReplaceAllString(body, `${1}"`+Redacted+`"`)
+	}
+	return body
+}
+
+func (r *redactor) redactForm(body string) string {
+	pairs := strings.Split(body, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if r.fields[strings.ToLower(name)] {
+			pairs[i] = key + "=" + url.QueryEscape(Redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// textual reports whether a body of contentType is worth capturing; binary
+// bodies such as files and archives are not
+func textual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, kind := range []string{"json", "xml", "text/", "x-www-form-urlencoded", "graphql"} {
+		if strings.Contains(contentType, kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSampleRate is the share of successful requests logged for
+	// organizations without settings
+	DefaultSampleRate = 0.01
+	// DefaultMaxBodyBytes and MaxBodyBytes bound the captured part of each
+	// body
+	DefaultMaxBodyBytes = 16 << 10
+	MaxBodyBytes        = 256 << 10
+	// FlushInterval is how often queued requests are saved
+	FlushInterval = time.Second
+	// PurgeInterval is how often requests past Retention are deleted
+	PurgeInterval = time.Hour
+	// Retention is how long logged requests are kept
+	Retention = 30 * 24 * time.Hour
+	// DefaultSearchLimit and MaxSearchLimit bound search pages
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 500
+	// ReplayHeader is set on replayed requests to the ID of the logged one
+	ReplayHeader = "X-Request-Log-Replay"
+	// replayTimeout bounds a replay
+	replayTimeout = 30 * time.Second
+	// queueSize bounds the requests waiting to be saved; more are dropped
+	queueSize = 1000
+	// flushBatchSize bounds the requests saved per statement
+	flushBatchSize = 100
+	// settingsFreshFor is how long an organization's settings are used
+	// before they are read again
+	settingsFreshFor = time.Minute
+)
+
+var (
+	// ErrInvalid wraps validation failures of settings and searches
+	ErrInvalid = errors.New("invalid request")
+	// ErrForbidden is returned when the caller is not a platform administrator
+	ErrForbidden = errors.New("request logs are only available to platform administrators")
+	// ErrNotReplayable is returned when replaying a request that did not
+	// fail or was not captured in full
+	ErrNotReplayable = errors.New("request cannot be replayed")
+	// ErrReplayUnavailable is returned when no handler is set to replay
+	// requests with
+	ErrReplayUnavailable = errors.New("request replay is not configured")
+)
+
+// RequestLogService logs a sample of the API's requests, every failed one,
+// for platform administrators to search and replay in sandboxes
+type RequestLogService struct {
+	repo     repository.RequestLogRepo
+	settings *cache.SWR[types.Settings]
+	replay   http.Handler
+	logger   *slog.Logger
+	now      func() time.Time
+	sample   func() float64
+
+	queue   chan types.Entry
+	dropped atomic.Int64
+	flushMu sync.Mutex
+}
+
+func NewRequestLogService(repo repository.RequestLogRepo, logger *slog.Logger) *RequestLogService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RequestLogService{
+		repo:     repo,
+		settings: cache.NewSWR[types.Settings](cache.Options{FreshFor: settingsFreshFor, ServeStaleFor: 5 * settingsFreshFor}, logger),
+		logger:   logger,
+		now:      time.Now,
+		sample:   rand.Float64,
+		queue:    make(chan types.Entry, queueSize),
+	}
+}
+
+// SetReplayHandler sets the handler failed requests are replayed with,
+// normally the API router without its middleware
+func (s *RequestLogService) SetReplayHandler(handler http.Handler) {
+	s.replay = handler
+}
+
+// admin returns the caller when they are a platform administrator
+func admin(ctx context.Context) (*auth.AuthContext, error) {
+	authCtx, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !authCtx.IsSuperAdmin {
+		return nil, ErrForbidden
+	}
+	return authCtx, nil
+}
+
+// DefaultSettings are the settings of organizations that have not saved
+// their own
+func DefaultSettings(orgID uuid.UUID) types.Settings {
+	return types.Settings{
+		OrganizationID:      orgID,
+		Enabled:             true,
+		SampleRate:          DefaultSampleRate,
+		CaptureRequestBody:  true,
+		CaptureResponseBody: true,
+		MaxBodyBytes:        DefaultMaxBodyBytes,
+		RedactFields:        append([]string(nil), DefaultRedactFields...),
+	}
+}
+
+// loadSettings returns the organization's settings or the defaults
+func (s *RequestLogService) loadSettings(ctx context.Context, orgID uuid.UUID) (types.Settings, error) {
+	settings, err := s.repo.GetSettings(ctx, orgID)
+	if err != nil {
+		return types.Settings{}, err
+	}
+	if settings == nil {
+		return DefaultSettings(orgID), nil
+	}
+	return *settings, nil
+}
+
+// GetSettings returns an organization's capture settings
+func (s *RequestLogService) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	if _, err := admin(ctx); err != nil {
+		return nil, err
+	}
+	settings, err := s.loadSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateSettings replaces an organization's capture settings. Other
+// instances use them within a minute.
+func (s *RequestLogService) UpdateSettings(ctx context.Context, orgID uuid.UUID, req types.SettingsRequest) (*types.Settings, error) {
+	authCtx, err := admin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.SampleRate < 0 || req.SampleRate > 1 {
+		return nil, fmt.Errorf("%w: sample_rate must be between 0 and 1", ErrInvalid)
+	}
+	if req.MaxBodyBytes < 0 || req.MaxBodyBytes > MaxBodyBytes {
+		return nil, fmt.Errorf("%w: max_body_bytes must be between 0 and %d", ErrInvalid, MaxBodyBytes)
+	}
+	redactFields := []string{}
+	for _, field := range req.RedactFields {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			redactFields = append(redactFields, field)
+		}
+	}
+
+	settings, err := s.repo.SaveSettings(ctx, types.Settings{
+		OrganizationID:      orgID,
+		Enabled:             req.Enabled,
+		SampleRate:          req.SampleRate,
+		CaptureRequestBody:  req.CaptureRequestBody,
+		CaptureResponseBody: req.CaptureResponseBody,
+		MaxBodyBytes:        req.MaxBodyBytes,
+		RedactFields:        redactFields,
+		UpdatedBy:           &authCtx.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.settings.Invalidate(orgID.String())
+	return settings, nil
+}
+
+// Search returns the logged requests matching filter, newest first and
+// without their bodies
+func (s *RequestLogService) Search(ctx context.Context, filter types.Filter) ([]types.Entry, error) {
+	if _, err := admin(ctx); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSearchLimit
+	}
+	if filter.Limit > MaxSearchLimit {
+		filter.Limit = MaxSearchLimit
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalid)
+	}
+	if filter.MinStatus != 0 && filter.MaxStatus != 0 && filter.MinStatus > filter.MaxStatus {
+		return nil, fmt.Errorf("%w: min_status is after max_status", ErrInvalid)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalid)
+	}
+	filter.Method = strings.ToUpper(filter.Method)
+	return s.repo.Search(ctx, filter)
+}
+
+// GetEntry returns a logged request with its captured bodies
+func (s *RequestLogService) GetEntry(ctx context.Context, id uuid.UUID) (*types.Entry, error) {
+	if _, err := admin(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEntry(ctx, id)
+}
+
+// ListReplays returns a logged request's replays, newest first
+func (s *RequestLogService) ListReplays(ctx context.Context, id uuid.UUID) ([]types.Replay, error) {
+	if _, err := admin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetEntry(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListReplays(ctx, id)
+}
+
+// Replay sends a failed request again, as the user who made it, in the
+// sandbox paired with its organization, or the same sandbox when it was
+// made in one. Production data is never touched. Redacted headers are left
+// out and redacted body fields are sent as redacted. The outcome is recorded.
+func (s *RequestLogService) Replay(ctx context.Context, id uuid.UUID) (*types.Replay, error) {
+	authCtx, err := admin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.replay == nil {
+		return nil, ErrReplayUnavailable
+	}
+
+	entry, err := s.repo.GetEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case entry.Status < http.StatusBadRequest:
+		return nil, fmt.Errorf("%w: only failed requests are replayed", ErrNotReplayable)
+	case entry.OrganizationID == nil || entry.UserID == nil:
+		return nil, fmt.Errorf("%w: the request was not made by a user of an organization", ErrNotReplayable)
+	case entry.RequestBodyTruncated || (entry.RequestBody == nil && entry.RequestSize != 0):
+		return nil, fmt.Errorf("%w: the request body was not captured in full", ErrNotReplayable)
+	}
+
+	sandboxID := *entry.OrganizationID
+	if !entry.IsSandbox {
+		if sandboxID, err = s.repo.SandboxFor(ctx, *entry.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+
+	target := entry.Path
+	if entry.Query != "" {
+		target += "?" + entry.Query
+	}
+	var body []byte
+	if entry.RequestBody != nil {
+		body = []byte(*entry.RequestBody)
+	}
+
+	replayCtx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+	replayCtx = auth.WithAuthContext(replayCtx, &auth.AuthContext{
+		UserID:         *entry.UserID,
+		OrganizationID: sandboxID,
+		Roles:          entry.Roles,
+		IsSandbox:      true,
+	})
+	req, err := http.NewRequestWithContext(replayCtx, entry.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotReplayable, err)
+	}
+	for name, value := range entry.RequestHeaders {
+		if value != Redacted {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Set(ReplayHeader, entry.ID.String())
+
+	settings := s.settingsFor(ctx, sandboxID)
+	rec := newResponseRecorder(nil, maxBodyBytes(settings), true)
+	started := s.now()
+	s.replay.ServeHTTP(rec, req)
+
+	replay := types.Replay{
+		ID:              uuid.New(),
+		RequestLogID:    entry.ID,
+		OrganizationID:  sandboxID,
+		ReplayedBy:      authCtx.UserID,
+		Status:          rec.statusCode(),
+		DurationMs:      s.now().Sub(started).Milliseconds(),
+		ResponseHeaders: redactHeaders(rec.Header()),
+	}
+	replay.ResponseBody, replay.ResponseBodyTruncated = rec.captured(newRedactor(settings.RedactFields))
+
+	s.logger.Info("Request replayed in sandbox",
+		"request_log_id", entry.ID, "sandbox_id", sandboxID, "status", replay.Status, "replayed_by", authCtx.UserID)
+	return s.repo.CreateReplay(ctx, replay)
+}
+
+// Purge deletes the requests logged before the retention period
+func (s *RequestLogService) Purge(ctx context.Context) (int64, error) {
+	return s.repo.Purge(ctx, s.now().Add(-Retention))
+}
+
+// normalizeRoute replaces the IDs in path with :id so requests to the same
+// endpoint share a route
+func normalizeRoute(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if _, err := uuid.Parse(segment); err == nil {
+			segments[i] = ":id"
+			continue
+		}
+		if strings.Trim(segment, "0123456789") == "" {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapedPath returns the request's path as sent
+func escapedPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
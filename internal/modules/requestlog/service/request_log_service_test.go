@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/requestlog/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRequestLogRepo struct {
+	settings  map[uuid.UUID]types.Settings
+	entries   []types.Entry
+	sandboxes map[uuid.UUID]uuid.UUID
+	replays   []types.Replay
+}
+
+func newFakeRequestLogRepo() *fakeRequestLogRepo {
+	return &fakeRequestLogRepo{
+		settings:  make(map[uuid.UUID]types.Settings),
+		sandboxes: make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+func (f *fakeRequestLogRepo) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	s, ok := f.settings[orgID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (f *fakeRequestLogRepo) SaveSettings(ctx context.Context, settings types.Settings) (*types.Settings, error) {
+	f.settings[settings.OrganizationID] = settings
+	return &settings, nil
+}
+
+func (f *fakeRequestLogRepo) InsertEntries(ctx context.Context, entries []types.Entry) error {
+	f.entries = append(f.entries, entries...)
+	return nil
+}
+
+func (f *fakeRequestLogRepo) Search(ctx context.Context, filter types.Filter) ([]types.Entry, error) {
+	return f.entries, nil
+}
+
+func (f *fakeRequestLogRepo) GetEntry(ctx context.Context, id uuid.UUID) (*types.Entry, error) {
+	for _, e := range f.entries {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("request log %w", repository.ErrNotFound)
+}
+
+func (f *fakeRequestLogRepo) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRequestLogRepo) SandboxFor(ctx context.Context, orgID uuid.UUID) (uuid.UUID, error) {
+	sandboxID, ok := f.sandboxes[orgID]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("sandbox %w", repository.ErrNotFound)
+	}
+	return sandboxID, nil
+}
+
+func (f *fakeRequestLogRepo) CreateReplay(ctx context.Context, replay types.Replay) (*types.Replay, error) {
+	f.replays = append(f.replays, replay)
+	return &replay, nil
+}
+
+func (f *fakeRequestLogRepo) ListReplays(ctx context.Context, requestLogID uuid.UUID) ([]types.Replay, error) {
+	return f.replays, nil
+}
+
+func asUser(r *http.Request, authCtx *auth.AuthContext) *http.Request {
+	return r.WithContext(auth.WithAuthContext(r.Context(), authCtx))
+}
+
+// echoOrder fails orders without lines and echoes the body it read
+var echoOrder = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(string(body), `"lines"`) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, `{"error":"order has no lines","received":%s}`, body)
+		return
+	}
+	w.Write(body)
+})
+
+func TestMiddlewareLogsFailuresAndSamplesSuccesses(t *testing.T) {
+	repo := newFakeRequestLogRepo()
+	svc := NewRequestLogService(repo, nil)
+	svc.sample = func() float64 { return 0.5 }
+	orgID, userID := uuid.New(), uuid.New()
+	handler := svc.Middleware(echoOrder)
+
+	serve := func(body string) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/orders/"+uuid.NewString()+"/confirm", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer secret-token")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, asUser(r, &auth.AuthContext{OrganizationID: orgID, UserID: userID, Roles: []string{"sales"}}))
+		// The handler still reads the whole body
+		assert.Contains(t, w.Body.String(), body)
+	}
+
+	// Successes are only logged when sampled, at 1% by default
+	serve(`{"lines": [], "card_number": "4111111111111111"}`)
+	// Failures are always logged
+	serve(`{"customer": "acme", "password": "hunter2", "nested": {"cvv": 123}}`)
+
+	saved, err := svc.Flush(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, saved)
+	entry := repo.entries[0]
+	assert.Equal(t, http.StatusUnprocessableEntity, entry.Status)
+	assert.False(t, entry.Sampled)
+	assert.Equal(t, "/api/v1/orders/:id/confirm", entry.Route)
+	assert.Equal(t, orgID, *entry.OrganizationID)
+	assert.Equal(t, userID, *entry.UserID)
+	assert.Equal(t, Redacted, entry.RequestHeaders["Authorization"])
+	require.NotNil(t, entry.RequestBody)
+	assert.Equal(t, `{"customer": "acme", "password": "[REDACTED]", "nested": {"cvv": "[REDACTED]"}}`, *entry.RequestBody)
+	require.NotNil(t, entry.ResponseBody)
+	assert.NotContains(t, *entry.ResponseBody, "hunter2")
+
+	// Organizations can sample more, and capture less
+	repo.settings[orgID] = types.Settings{OrganizationID: orgID, Enabled: true, SampleRate: 0.75,
+		CaptureRequestBody: true, MaxBodyBytes: 8, RedactFields: DefaultRedactFields}
+	svc.settings.Invalidate(orgID.String())
+	serve(`{"lines": [1, 2, 3]}`)
+
+	saved, err = svc.Flush(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, saved)
+	entry = repo.entries[1]
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.True(t, entry.Sampled)
+	assert.Equal(t, `{"lines"`, *entry.RequestBody)
+	assert.True(t, entry.RequestBodyTruncated)
+	assert.Nil(t, entry.ResponseBody)
+}
+
+func TestReplayRunsFailedRequestInSandbox(t *testing.T) {
+	repo := newFakeRequestLogRepo()
+	svc := NewRequestLogService(repo, nil)
+	orgID, sandboxID, userID, adminID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo.sandboxes[orgID] = sandboxID
+
+	body := `{"customer": "acme"}`
+	failed := types.Entry{ID: uuid.New(), OrganizationID: &orgID, UserID: &userID, Roles: []string{"sales"},
+		Method: http.MethodPost, Path: "/api/v1/orders", Query: "draft=true", Status: http.StatusUnprocessableEntity,
+		RequestHeaders: map[string]string{"Content-Type": "application/json", "Authorization": Redacted},
+		RequestSize:    int64(len(body)), RequestBody: &body}
+	succeeded := failed
+	succeeded.ID, succeeded.Status = uuid.New(), http.StatusOK
+	repo.entries = []types.Entry{failed, succeeded}
+
+	var seen *auth.AuthContext
+	svc.SetReplayHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = auth.FromContext(r.Context())
+		assert.Equal(t, "true", r.URL.Query().Get("draft"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Equal(t, failed.ID.String(), r.Header.Get(ReplayHeader))
+		echoOrder.ServeHTTP(w, r)
+	}))
+
+	// Only platform administrators can replay
+	ctx := auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: adminID, OrganizationID: orgID})
+	_, err := svc.Replay(ctx, failed.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	ctx = auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: adminID, OrganizationID: uuid.New(), IsSuperAdmin: true})
+	_, err = svc.Replay(ctx, succeeded.ID)
+	assert.ErrorIs(t, err, ErrNotReplayable)
+
+	replay, err := svc.Replay(ctx, failed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, seen)
+	assert.Equal(t, sandboxID, seen.OrganizationID)
+	assert.Equal(t, userID, seen.UserID)
+	assert.Equal(t, []string{"sales"}, seen.Roles)
+	assert.True(t, seen.IsSandbox)
+
+	assert.Equal(t, sandboxID, replay.OrganizationID)
+	assert.Equal(t, adminID, replay.ReplayedBy)
+	assert.Equal(t, http.StatusUnprocessableEntity, replay.Status)
+	require.NotNil(t, replay.ResponseBody)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(*replay.ResponseBody), &response))
+	assert.Equal(t, "order has no lines", response["error"])
+	assert.Len(t, repo.replays, 1)
+
+	// Organizations without a sandbox cannot replay
+	delete(repo.sandboxes, orgID)
+	_, err = svc.Replay(ctx, failed.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Settings control which of an organization's requests are logged and how
+// much of them is kept
+type Settings struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Enabled        bool      `json:"enabled"`
+	// SampleRate is the share of successful requests logged, from 0 to 1.
+	// Failed requests are always logged.
+	SampleRate          float64    `json:"sample_rate"`
+	CaptureRequestBody  bool       `json:"capture_request_body"`
+	CaptureResponseBody bool       `json:"capture_response_body"`
+	MaxBodyBytes        int        `json:"max_body_bytes"`
+	RedactFields        []string   `json:"redact_fields"`
+	UpdatedBy           *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+}
+
+// SettingsRequest changes an organization's settings
+type SettingsRequest struct {
+	Enabled             bool     `json:"enabled"`
+	SampleRate          float64  `json:"sample_rate"`
+	CaptureRequestBody  bool     `json:"capture_request_body"`
+	CaptureResponseBody bool     `json:"capture_response_body"`
+	MaxBodyBytes        int      `json:"max_body_bytes"`
+	RedactFields        []string `json:"redact_fields"`
+}
+
+// Entry is a logged request. Bodies are nil when they were not captured.
+type Entry struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID *uuid.UUID        `json:"organization_id,omitempty"`
+	UserID         *uuid.UUID        `json:"user_id,omitempty"`
+	Roles          []string          `json:"roles,omitempty"`
+	IsSandbox      bool              `json:"is_sandbox"`
+	Method         string            `json:"method"`
+	Route          string            `json:"route"`
+	Path           string            `json:"path"`
+	Query          string            `json:"query,omitempty"`
+	Status         int               `json:"status"`
+	DurationMs     int64             `json:"duration_ms"`
+	ClientIP       string            `json:"client_ip,omitempty"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	// RequestSize is the request's Content-Length, -1 when unknown
+	RequestSize           int64   `json:"request_size"`
+	RequestBody           *string `json:"request_body,omitempty"`
+	RequestBodyTruncated  bool    `json:"request_body_truncated"`
+	ResponseBody          *string `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool    `json:"response_body_truncated"`
+	// Sampled is set on successful requests, logged by sampling
+	Sampled   bool      `json:"sampled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Filter selects logged requests. Route ending in * matches routes starting
+// with the rest.
+type Filter struct {
+	OrganizationID *uuid.UUID
+	Route          string
+	Method         string
+	Status         int
+	MinStatus      int
+	MaxStatus      int
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// Replay is a failed request sent again in a sandbox organization
+type Replay struct {
+	ID                    uuid.UUID         `json:"id"`
+	RequestLogID          uuid.UUID         `json:"request_log_id"`
+	OrganizationID        uuid.UUID         `json:"organization_id"`
+	ReplayedBy            uuid.UUID         `json:"replayed_by"`
+	Status                int               `json:"status"`
+	DurationMs            int64             `json:"duration_ms"`
+	ResponseHeaders       map[string]string `json:"response_headers,omitempty"`
+	ResponseBody          *string           `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool              `json:"response_body_truncated"`
+	CreatedAt             time.Time         `json:"created_at"`
+}
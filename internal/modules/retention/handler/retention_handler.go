@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RetentionHandler handles HTTP requests for data retention policies and legal holds
+type RetentionHandler struct {
+	service *service.RetentionService
+}
+
+func NewRetentionHandler(service *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{service: service}
+}
+
+func (h *RetentionHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/retention/policies", h.ListPolicies)
+	router.PUT("/api/v1/retention/policies/:entity_class", h.UpdatePolicy)
+	router.GET("/api/v1/retention/preview", h.Preview)
+	router.POST("/api/v1/retention/enforce", h.Enforce)
+	router.GET("/api/v1/retention/legal-holds", h.ListLegalHolds)
+	router.POST("/api/v1/retention/legal-holds", h.PlaceLegalHold)
+	router.DELETE("/api/v1/retention/legal-holds/:id", h.ReleaseLegalHold)
+}
+
+// ListPolicies handles GET /api/v1/retention/policies
+func (h *RetentionHandler) ListPolicies(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	policies, err := h.service.ListPolicies(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// UpdatePolicy handles PUT /api/v1/retention/policies/:entity_class
+func (h *RetentionHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	class := types.EntityClass(ps.ByName("entity_class"))
+	if !class.IsValid() {
+		http.Error(w, "Invalid entity class", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RetentionPolicyUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.service.UpdatePolicy(r.Context(), authCtx.OrganizationID, authCtx.UserID, class, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// Preview handles GET /api/v1/retention/preview
+func (h *RetentionHandler) Preview(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Preview(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Enforce handles POST /api/v1/retention/enforce
+func (h *RetentionHandler) Enforce(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := h.service.Enforce(r.Context(), authCtx.OrganizationID)
+	if err != nil && runs == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Partial failures are reported per entity class in the run list
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// ListLegalHolds handles GET /api/v1/retention/legal-holds?active=true
+func (h *RetentionHandler) ListLegalHolds(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	activeOnly := true
+	if active := r.URL.Query().Get("active"); active != "" {
+		val, err := strconv.ParseBool(active)
+		if err != nil {
+			http.Error(w, "Invalid active value", http.StatusBadRequest)
+			return
+		}
+		activeOnly = val
+	}
+
+	holds, err := h.service.ListLegalHolds(r.Context(), authCtx.OrganizationID, activeOnly)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// PlaceLegalHold handles POST /api/v1/retention/legal-holds
+func (h *RetentionHandler) PlaceLegalHold(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.LegalHoldCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hold, err := h.service.PlaceLegalHold(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ReleaseLegalHold handles DELETE /api/v1/retention/legal-holds/:id
+func (h *RetentionHandler) ReleaseLegalHold(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	holdID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid legal hold ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ReleaseLegalHold(r.Context(), authCtx.OrganizationID, authCtx.UserID, holdID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
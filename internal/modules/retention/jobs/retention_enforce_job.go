@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeRetentionEnforce = "retention.enforce"
+
+// RetentionEnforceJobHandler handles queued retention enforcement jobs
+type RetentionEnforceJobHandler struct {
+	retentionService *service.RetentionService
+}
+
+func NewRetentionEnforceJobHandler(retentionService *service.RetentionService) *RetentionEnforceJobHandler {
+	return &RetentionEnforceJobHandler{
+		retentionService: retentionService,
+	}
+}
+
+// Handle processes a retention enforcement job
+func (h *RetentionEnforceJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.retentionService.EnforceAll(ctx); err != nil {
+		return fmt.Errorf("failed to enforce retention policies: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *RetentionEnforceJobHandler) JobType() string {
+	return JobTypeRetentionEnforce
+}
+
+// Scheduler runs retention enforcement on a fixed interval
+type Scheduler struct {
+	handler     *RetentionEnforceJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *RetentionEnforceJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// NextRun returns when the next scheduled enforcement is due. Runs are
+// aligned to multiples of the interval, so every instance agrees on it.
+func (s *Scheduler) NextRun() time.Time {
+	return time.Now().Truncate(s.interval).Add(s.interval)
+}
+
+// Start runs enforcement every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeRetentionEnforce, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		s.logger.Info("Running scheduled retention enforcement")
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeRetentionEnforce, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled retention enforcement failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule retention enforcement", "error", err)
+	}
+}
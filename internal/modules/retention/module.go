@@ -0,0 +1,85 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RetentionModule represents the data retention module
+type RetentionModule struct {
+	retentionService *service.RetentionService
+	retentionHandler *handler.RetentionHandler
+	scheduler        *jobs.Scheduler
+	logger           *slog.Logger
+}
+
+// NewRetentionModule creates a new retention module
+func NewRetentionModule() *RetentionModule {
+	return &RetentionModule{}
+}
+
+// Name returns the module name
+func (m *RetentionModule) Name() string {
+	return "retention"
+}
+
+// Init initializes the retention module and starts scheduled enforcement
+func (m *RetentionModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "retention")
+	m.logger.Info("Initializing retention module")
+
+	// Create repositories
+	retentionRepo := repository.NewRetentionRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.retentionService = service.NewRetentionService(retentionRepo, authAdapter, m.logger)
+
+	// Create scheduled enforcement
+	enforceJobHandler := jobs.NewRetentionEnforceJobHandler(m.retentionService)
+	m.scheduler = jobs.NewScheduler(enforceJobHandler, service.RunInterval, deps.Scheduler, m.logger)
+	m.retentionService.SetNextRunFunc(m.scheduler.NextRun)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.retentionHandler = handler.NewRetentionHandler(m.retentionService)
+
+	m.logger.Info("Retention module initialized successfully")
+	return nil
+}
+
+// SetAuditTrailRetention keeps audit logs for at least the audit trail
+// retention configured for compliance. It must be called after Init.
+func (m *RetentionModule) SetAuditTrailRetention(auditTrail service.AuditTrailRetention) {
+	if m.retentionService != nil {
+		m.retentionService.SetAuditTrailRetention(auditTrail)
+	}
+}
+
+// RegisterRoutes registers retention module routes
+func (m *RetentionModule) RegisterRoutes(router interface{}) {
+	if m.retentionHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.retentionHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the retention module
+func (m *RetentionModule) RegisterEventHandlers(bus interface{}) {
+	// Enforcement is schedule driven; no events needed
+}
+
+// Health checks the health of the retention module
+func (m *RetentionModule) Health() error {
+	return nil
+}
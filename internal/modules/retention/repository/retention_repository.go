@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/types"
+
+	"github.com/google/uuid"
+)
+
+// RetentionRepo defines the interface for retention repository operations
+type RetentionRepo interface {
+	ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.RetentionPolicy, error)
+	UpsertPolicy(ctx context.Context, policy types.RetentionPolicy) (*types.RetentionPolicy, error)
+	ListLegalHolds(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.LegalHold, error)
+	CreateLegalHold(ctx context.Context, hold types.LegalHold) (*types.LegalHold, error)
+	ReleaseLegalHold(ctx context.Context, orgID, holdID, releasedBy uuid.UUID) error
+	CountPurgeable(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time) (purge int, held int, err error)
+	PurgeBatch(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time, batchSize int) (int, error)
+	CreateRun(ctx context.Context, run types.RetentionRun) error
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// RetentionRepository persists retention policies and legal holds and purges expired records
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+// Ensure RetentionRepository implements RetentionRepo interface
+var _ RetentionRepo = &RetentionRepository{}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// retentionSources maps each entity class to the table holding its records.
+// age is the expression compared with the cutoff; scope narrows the table to
+// the records the class covers.
+var retentionSources = map[types.EntityClass]struct {
+	table string
+	age   string
+	scope string
+}{
+	types.EntityClassTrackingEvents: {table: "delivery_tracking_events", age: "t.event_time"},
+	types.EntityClassAuditLogs:      {table: "permission_audit_log", age: "t.created_at"},
+	types.EntityClassLostLeads:      {table: "leads", age: "COALESCE(t.date_closed, t.updated_at)", scope: "t.status = 'lost'"},
+	types.EntityClassDeletedLeads:   {table: "leads", age: "t.deleted_at", scope: "t.deleted_at IS NOT NULL"},
+}
+
+// expiredPredicate returns the predicate selecting expired records of a class.
+// $1 = organization, $2 = cutoff, $3 = entity class
+func expiredPredicate(class types.EntityClass) (string, string, error) {
+	src, ok := retentionSources[class]
+	if !ok {
+		return "", "", fmt.Errorf("unknown entity class: %s", class)
+	}
+	predicate := fmt.Sprintf("t.organization_id = $1 AND %s < $2", src.age)
+	if src.scope != "" {
+		predicate += " AND " + src.scope
+	}
+	return src.table, predicate, nil
+}
+
+// heldPredicate matches records covered by an active legal hold
+const heldPredicate = `EXISTS (
+		SELECT 1 FROM retention_legal_holds h
+		WHERE h.organization_id = t.organization_id
+			AND h.entity_class = $3
+			AND h.released_at IS NULL
+			AND (h.record_id IS NULL OR h.record_id = t.id)
+	)`
+
+func (r *RetentionRepository) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.RetentionPolicy, error) {
+	query := `
+		SELECT id, organization_id, entity_class, retention_days, enabled, created_at, updated_at, updated_by
+		FROM retention_policies
+		WHERE organization_id = $1
+		ORDER BY entity_class
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []types.RetentionPolicy
+	for rows.Next() {
+		var p types.RetentionPolicy
+		if err := rows.Scan(&p.ID, &p.OrganizationID, &p.EntityClass, &p.RetentionDays, &p.Enabled, &p.CreatedAt, &p.UpdatedAt, &p.UpdatedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during retention policy iteration: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *RetentionRepository) UpsertPolicy(ctx context.Context, policy types.RetentionPolicy) (*types.RetentionPolicy, error) {
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO retention_policies (id, organization_id, entity_class, retention_days, enabled, created_at, updated_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW(), $6)
+		ON CONFLICT (organization_id, entity_class) DO UPDATE SET
+			retention_days = EXCLUDED.retention_days,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW(),
+			updated_by = EXCLUDED.updated_by
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		policy.ID, policy.OrganizationID, policy.EntityClass, policy.RetentionDays, policy.Enabled, policy.UpdatedBy,
+	).Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save retention policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+func (r *RetentionRepository) ListLegalHolds(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.LegalHold, error) {
+	query := `
+		SELECT id, organization_id, entity_class, record_id, reason, placed_by, created_at, released_at, released_by
+		FROM retention_legal_holds
+		WHERE organization_id = $1
+	`
+	if activeOnly {
+		query += " AND released_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []types.LegalHold
+	for rows.Next() {
+		var h types.LegalHold
+		if err := rows.Scan(&h.ID, &h.OrganizationID, &h.EntityClass, &h.RecordID, &h.Reason, &h.PlacedBy, &h.CreatedAt, &h.ReleasedAt, &h.ReleasedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold: %w", err)
+		}
+		holds = append(holds, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during legal hold iteration: %w", err)
+	}
+
+	return holds, nil
+}
+
+func (r *RetentionRepository) CreateLegalHold(ctx context.Context, hold types.LegalHold) (*types.LegalHold, error) {
+	if hold.ID == uuid.Nil {
+		hold.ID = uuid.New()
+	}
+	if hold.CreatedAt.IsZero() {
+		hold.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO retention_legal_holds (id, organization_id, entity_class, record_id, reason, placed_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		hold.ID, hold.OrganizationID, hold.EntityClass, hold.RecordID, hold.Reason, hold.PlacedBy, hold.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create legal hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+func (r *RetentionRepository) ReleaseLegalHold(ctx context.Context, orgID, holdID, releasedBy uuid.UUID) error {
+	query := `
+		UPDATE retention_legal_holds
+		SET released_at = NOW(), released_by = $3
+		WHERE id = $1 AND organization_id = $2 AND released_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, holdID, orgID, releasedBy)
+	if err != nil {
+		return fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("legal hold not found or already released")
+	}
+
+	return nil
+}
+
+// CountPurgeable counts expired records, split into those that will be purged
+// and those retained because of a legal hold
+func (r *RetentionRepository) CountPurgeable(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time) (int, int, error) {
+	table, predicate, err := expiredPredicate(class)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) FILTER (WHERE NOT %[3]s),
+			COUNT(*) FILTER (WHERE %[3]s)
+		FROM %[1]s t
+		WHERE %[2]s
+	`, table, predicate, heldPredicate)
+
+	var purge, held int
+	if err := r.db.QueryRowContext(ctx, query, orgID, cutoff, class).Scan(&purge, &held); err != nil {
+		return 0, 0, fmt.Errorf("failed to count expired %s: %w", class, err)
+	}
+
+	return purge, held, nil
+}
+
+// PurgeBatch deletes up to batchSize expired records that are not under a legal hold
+func (r *RetentionRepository) PurgeBatch(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time, batchSize int) (int, error) {
+	table, predicate, err := expiredPredicate(class)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE id IN (
+			SELECT t.id FROM %[1]s t
+			WHERE %[2]s AND NOT %[3]s
+			LIMIT $4
+		)
+	`, table, predicate, heldPredicate)
+
+	result, err := r.db.ExecContext(ctx, query, orgID, cutoff, class, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired %s: %w", class, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (r *RetentionRepository) CreateRun(ctx context.Context, run types.RetentionRun) error {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO retention_runs (id, organization_id, entity_class, cutoff, purged_count, held_count, status, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		run.ID, run.OrganizationID, run.EntityClass, run.Cutoff, run.PurgedCount, run.HeldCount,
+		run.Status, run.Error, run.StartedAt, run.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record retention run: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RetentionRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during organization iteration: %w", err)
+	}
+
+	return ids, nil
+}
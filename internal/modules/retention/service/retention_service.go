@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PurgeBatchSize is the number of records deleted per statement during enforcement
+	PurgeBatchSize = 1000
+	// RunInterval is how often scheduled enforcement runs
+	RunInterval = 24 * time.Hour
+)
+
+// AuthService defines the permission check used by the retention service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// AuditTrailRetention tells how long an organization keeps its audit trail,
+// which is configured apart from its retention policies
+type AuditTrailRetention interface {
+	AuditRetentionDays(ctx context.Context, orgID uuid.UUID) (int, error)
+}
+
+// RetentionService manages retention policies and legal holds and enforces them
+type RetentionService struct {
+	repo        repository.RetentionRepo
+	authService AuthService
+	auditTrail  AuditTrailRetention
+	logger      *slog.Logger
+	now         func() time.Time
+	nextRunAt   func() time.Time
+}
+
+func NewRetentionService(repo repository.RetentionRepo, authService AuthService, logger *slog.Logger) *RetentionService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &RetentionService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+	s.nextRunAt = func() time.Time { return s.now().Add(RunInterval) }
+	return s
+}
+
+// SetNextRunFunc lets the scheduler report when the next enforcement run is due
+func (s *RetentionService) SetNextRunFunc(fn func() time.Time) {
+	s.nextRunAt = fn
+}
+
+// SetAuditTrailRetention keeps audit logs for at least the audit trail
+// retention, whatever their retention policy says
+func (s *RetentionService) SetAuditTrailRetention(auditTrail AuditTrailRetention) {
+	s.auditTrail = auditTrail
+}
+
+// cutoff returns the instant before which the policy's records are purged.
+// Audit logs are never purged before the audit trail retention has passed.
+func (s *RetentionService) cutoff(ctx context.Context, policy types.RetentionPolicy, at time.Time) (time.Time, error) {
+	cutoff := policy.Cutoff(at)
+	if policy.EntityClass != types.EntityClassAuditLogs || s.auditTrail == nil {
+		return cutoff, nil
+	}
+	days, err := s.auditTrail.AuditRetentionDays(ctx, policy.OrganizationID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load audit trail retention: %w", err)
+	}
+	if trail := at.AddDate(0, 0, -days); trail.Before(cutoff) {
+		cutoff = trail
+	}
+	return cutoff, nil
+}
+
+// ResolvePolicies merges organization overrides with the default policies.
+// Every known entity class is returned exactly once, in AllEntityClasses order.
+func ResolvePolicies(orgID uuid.UUID, overrides []types.RetentionPolicy) []types.RetentionPolicy {
+	byClass := make(map[types.EntityClass]types.RetentionPolicy, len(overrides))
+	for _, p := range overrides {
+		byClass[p.EntityClass] = p
+	}
+
+	policies := make([]types.RetentionPolicy, 0, len(types.AllEntityClasses))
+	for _, class := range types.AllEntityClasses {
+		if p, ok := byClass[class]; ok {
+			policies = append(policies, p)
+			continue
+		}
+		policies = append(policies, types.RetentionPolicy{
+			OrganizationID: orgID,
+			EntityClass:    class,
+			RetentionDays:  types.DefaultRetentionDays[class],
+			Enabled:        true,
+			IsDefault:      true,
+		})
+	}
+	return policies
+}
+
+// ListPolicies returns the effective policy for every entity class
+func (s *RetentionService) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.RetentionPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.resolvePolicies(ctx, orgID)
+}
+
+func (s *RetentionService) resolvePolicies(ctx context.Context, orgID uuid.UUID) ([]types.RetentionPolicy, error) {
+	overrides, err := s.repo.ListPolicies(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention policies: %w", err)
+	}
+	return ResolvePolicies(orgID, overrides), nil
+}
+
+// UpdatePolicy configures the retention for one entity class
+func (s *RetentionService) UpdatePolicy(ctx context.Context, orgID, userID uuid.UUID, class types.EntityClass, req types.RetentionPolicyUpdateRequest) (*types.RetentionPolicy, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !class.IsValid() {
+		return nil, fmt.Errorf("invalid entity class: %s", class)
+	}
+	if min := types.MinRetentionDays[class]; req.RetentionDays < min {
+		return nil, fmt.Errorf("retention for %s must be at least %d days", class, min)
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	return s.repo.UpsertPolicy(ctx, types.RetentionPolicy{
+		OrganizationID: orgID,
+		EntityClass:    class,
+		RetentionDays:  req.RetentionDays,
+		Enabled:        enabled,
+		UpdatedBy:      &userID,
+	})
+}
+
+// ListLegalHolds returns legal holds for the organization
+func (s *RetentionService) ListLegalHolds(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.LegalHold, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListLegalHolds(ctx, orgID, activeOnly)
+}
+
+// PlaceLegalHold exempts a record, or a whole entity class, from enforcement
+func (s *RetentionService) PlaceLegalHold(ctx context.Context, orgID, userID uuid.UUID, req types.LegalHoldCreateRequest) (*types.LegalHold, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !req.EntityClass.IsValid() {
+		return nil, fmt.Errorf("invalid entity class: %s", req.EntityClass)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to place a legal hold")
+	}
+
+	return s.repo.CreateLegalHold(ctx, types.LegalHold{
+		OrganizationID: orgID,
+		EntityClass:    req.EntityClass,
+		RecordID:       req.RecordID,
+		Reason:         reason,
+		PlacedBy:       userID,
+	})
+}
+
+// ReleaseLegalHold lifts a legal hold; the records become eligible at the next run
+func (s *RetentionService) ReleaseLegalHold(ctx context.Context, orgID, userID, holdID uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "retention:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ReleaseLegalHold(ctx, orgID, holdID, userID)
+}
+
+// Preview reports what the next enforcement run will purge
+func (s *RetentionService) Preview(ctx context.Context, orgID uuid.UUID) (*types.PurgeReport, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	policies, err := s.resolvePolicies(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := s.repo.ListLegalHolds(ctx, orgID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+	classHeld := classWideHolds(holds)
+
+	// The preview is computed against the next run's clock so the counts
+	// match what that run will actually delete.
+	nextRun := s.nextRunAt()
+	report := &types.PurgeReport{
+		OrganizationID: orgID,
+		NextRunAt:      nextRun,
+		Classes:        make([]types.PurgePreview, 0, len(policies)),
+	}
+
+	for _, policy := range policies {
+		cutoff, err := s.cutoff(ctx, policy, nextRun)
+		if err != nil {
+			return nil, err
+		}
+		preview := types.PurgePreview{
+			EntityClass:   policy.EntityClass,
+			RetentionDays: policy.RetentionDays,
+			Enabled:       policy.Enabled,
+			Cutoff:        cutoff,
+			ClassHeld:     classHeld[policy.EntityClass],
+		}
+
+		purge, held, err := s.repo.CountPurgeable(ctx, orgID, policy.EntityClass, preview.Cutoff)
+		if err != nil {
+			return nil, err
+		}
+		preview.HeldCount = held
+		if policy.Enabled {
+			preview.PurgeCount = purge
+		}
+
+		report.Classes = append(report.Classes, preview)
+	}
+
+	return report, nil
+}
+
+// Enforce purges expired records for one organization
+func (s *RetentionService) Enforce(ctx context.Context, orgID uuid.UUID) ([]types.RetentionRun, error) {
+	if err := s.authService.CheckPermission(ctx, "retention:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.enforce(ctx, orgID)
+}
+
+// EnforceAll purges expired records for every organization. It is invoked by
+// the scheduled job and therefore bypasses the per-request permission check.
+func (s *RetentionService) EnforceAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.enforce(ctx, orgID); err != nil {
+			failed++
+			s.logger.Error("Retention enforcement failed", "organization_id", orgID, "error", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("retention enforcement failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *RetentionService) enforce(ctx context.Context, orgID uuid.UUID) ([]types.RetentionRun, error) {
+	policies, err := s.resolvePolicies(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	runs := make([]types.RetentionRun, 0, len(policies))
+	var runErr error
+
+	for _, policy := range policies {
+		run := types.RetentionRun{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			EntityClass:    policy.EntityClass,
+			Cutoff:         policy.Cutoff(now),
+			StartedAt:      s.now(),
+		}
+
+		if !policy.Enabled {
+			run.Status = types.RetentionRunStatusSkipped
+		} else if err := s.purge(ctx, policy, now, &run); err != nil {
+			msg := err.Error()
+			run.Status = types.RetentionRunStatusFailed
+			run.Error = &msg
+			runErr = err
+		} else {
+			run.Status = types.RetentionRunStatusCompleted
+		}
+		run.FinishedAt = s.now()
+
+		if err := s.repo.CreateRun(ctx, run); err != nil {
+			s.logger.Error("Failed to record retention run", "organization_id", orgID, "entity_class", run.EntityClass, "error", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, runErr
+}
+
+func (s *RetentionService) purge(ctx context.Context, policy types.RetentionPolicy, now time.Time, run *types.RetentionRun) error {
+	cutoff, err := s.cutoff(ctx, policy, now)
+	if err != nil {
+		return err
+	}
+	run.Cutoff = cutoff
+
+	_, held, err := s.repo.CountPurgeable(ctx, run.OrganizationID, run.EntityClass, run.Cutoff)
+	if err != nil {
+		return err
+	}
+	run.HeldCount = held
+
+	for {
+		deleted, err := s.repo.PurgeBatch(ctx, run.OrganizationID, run.EntityClass, run.Cutoff, PurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		run.PurgedCount += deleted
+		if deleted < PurgeBatchSize {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func classWideHolds(holds []types.LegalHold) map[types.EntityClass]bool {
+	held := make(map[types.EntityClass]bool)
+	for _, h := range holds {
+		if h.RecordID == nil && h.ReleasedAt == nil {
+			held[h.EntityClass] = true
+		}
+	}
+	return held
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/retention/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRetentionRepo struct {
+	policies  []types.RetentionPolicy
+	holds     []types.LegalHold
+	purgeable map[types.EntityClass]int
+	held      map[types.EntityClass]int
+	purgeErr  error
+
+	cutoffs   map[types.EntityClass]time.Time
+	batches   int
+	runs      []types.RetentionRun
+	upserted  []types.RetentionPolicy
+	createdHs []types.LegalHold
+}
+
+func newFakeRetentionRepo() *fakeRetentionRepo {
+	return &fakeRetentionRepo{
+		purgeable: make(map[types.EntityClass]int),
+		held:      make(map[types.EntityClass]int),
+		cutoffs:   make(map[types.EntityClass]time.Time),
+	}
+}
+
+func (f *fakeRetentionRepo) ListPolicies(ctx context.Context, orgID uuid.UUID) ([]types.RetentionPolicy, error) {
+	return f.policies, nil
+}
+
+func (f *fakeRetentionRepo) UpsertPolicy(ctx context.Context, policy types.RetentionPolicy) (*types.RetentionPolicy, error) {
+	f.upserted = append(f.upserted, policy)
+	return &policy, nil
+}
+
+func (f *fakeRetentionRepo) ListLegalHolds(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.LegalHold, error) {
+	return f.holds, nil
+}
+
+func (f *fakeRetentionRepo) CreateLegalHold(ctx context.Context, hold types.LegalHold) (*types.LegalHold, error) {
+	f.createdHs = append(f.createdHs, hold)
+	return &hold, nil
+}
+
+func (f *fakeRetentionRepo) ReleaseLegalHold(ctx context.Context, orgID, holdID, releasedBy uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeRetentionRepo) CountPurgeable(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time) (int, int, error) {
+	f.cutoffs[class] = cutoff
+	return f.purgeable[class], f.held[class], nil
+}
+
+func (f *fakeRetentionRepo) PurgeBatch(ctx context.Context, orgID uuid.UUID, class types.EntityClass, cutoff time.Time, batchSize int) (int, error) {
+	if f.purgeErr != nil {
+		return 0, f.purgeErr
+	}
+	f.batches++
+	n := f.purgeable[class]
+	if n > batchSize {
+		n = batchSize
+	}
+	f.purgeable[class] -= n
+	return n, nil
+}
+
+func (f *fakeRetentionRepo) CreateRun(ctx context.Context, run types.RetentionRun) error {
+	f.runs = append(f.runs, run)
+	return nil
+}
+
+func (f *fakeRetentionRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return []uuid.UUID{uuid.New()}, nil
+}
+
+type fakeAuth struct {
+	permErr error
+}
+
+func (f *fakeAuth) CheckPermission(ctx context.Context, permission string) error {
+	return f.permErr
+}
+
+func TestResolvePoliciesAppliesDefaults(t *testing.T) {
+	orgID := uuid.New()
+	override := types.RetentionPolicy{
+		OrganizationID: orgID,
+		EntityClass:    types.EntityClassTrackingEvents,
+		RetentionDays:  30,
+		Enabled:        true,
+	}
+
+	policies := ResolvePolicies(orgID, []types.RetentionPolicy{override})
+	require.Len(t, policies, len(types.AllEntityClasses))
+
+	assert.Equal(t, 30, policies[0].RetentionDays)
+	assert.False(t, policies[0].IsDefault)
+	assert.Equal(t, 7*365, policies[1].RetentionDays)
+	assert.True(t, policies[1].IsDefault)
+	assert.Equal(t, 3*365, policies[2].RetentionDays)
+	assert.True(t, policies[2].IsDefault)
+	assert.Equal(t, types.EntityClassDeletedLeads, policies[3].EntityClass)
+	assert.Equal(t, 30, policies[3].RetentionDays)
+	assert.True(t, policies[3].IsDefault)
+}
+
+func TestUpdatePolicyEnforcesMinimum(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+
+	_, err := svc.UpdatePolicy(context.Background(), uuid.New(), uuid.New(), types.EntityClassAuditLogs,
+		types.RetentionPolicyUpdateRequest{RetentionDays: 30})
+	assert.Error(t, err)
+	assert.Empty(t, repo.upserted)
+
+	_, err = svc.UpdatePolicy(context.Background(), uuid.New(), uuid.New(), types.EntityClass("invoices"),
+		types.RetentionPolicyUpdateRequest{RetentionDays: 400})
+	assert.Error(t, err)
+
+	policy, err := svc.UpdatePolicy(context.Background(), uuid.New(), uuid.New(), types.EntityClassAuditLogs,
+		types.RetentionPolicyUpdateRequest{RetentionDays: 400})
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	assert.Len(t, repo.upserted, 1)
+}
+
+func TestUpdatePolicyRequiresPermission(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	svc := NewRetentionService(repo, &fakeAuth{permErr: errors.New("forbidden")}, nil)
+
+	_, err := svc.UpdatePolicy(context.Background(), uuid.New(), uuid.New(), types.EntityClassLostLeads,
+		types.RetentionPolicyUpdateRequest{RetentionDays: 365})
+	assert.Error(t, err)
+	assert.Empty(t, repo.upserted)
+}
+
+func TestPreviewUsesNextRunAndSkipsDisabled(t *testing.T) {
+	orgID := uuid.New()
+	nextRun := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	repo := newFakeRetentionRepo()
+	repo.policies = []types.RetentionPolicy{
+		{OrganizationID: orgID, EntityClass: types.EntityClassLostLeads, RetentionDays: 365, Enabled: false},
+	}
+	repo.holds = []types.LegalHold{{EntityClass: types.EntityClassAuditLogs}}
+	repo.purgeable[types.EntityClassTrackingEvents] = 12
+	repo.held[types.EntityClassTrackingEvents] = 3
+	repo.purgeable[types.EntityClassLostLeads] = 5
+
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+	svc.SetNextRunFunc(func() time.Time { return nextRun })
+
+	report, err := svc.Preview(context.Background(), orgID)
+	require.NoError(t, err)
+	assert.Equal(t, nextRun, report.NextRunAt)
+	require.Len(t, report.Classes, 4)
+
+	tracking := report.Classes[0]
+	assert.Equal(t, 12, tracking.PurgeCount)
+	assert.Equal(t, 3, tracking.HeldCount)
+	assert.Equal(t, nextRun.AddDate(0, 0, -90), repo.cutoffs[types.EntityClassTrackingEvents])
+
+	assert.True(t, report.Classes[1].ClassHeld)
+
+	lostLeads := report.Classes[2]
+	assert.False(t, lostLeads.Enabled)
+	assert.Equal(t, 0, lostLeads.PurgeCount)
+}
+
+func TestEnforcePurgesInBatches(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	repo.purgeable[types.EntityClassTrackingEvents] = PurgeBatchSize*2 + 10
+
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+
+	runs, err := svc.Enforce(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, runs, 4)
+	assert.Equal(t, PurgeBatchSize*2+10, runs[0].PurgedCount)
+	assert.Equal(t, types.RetentionRunStatusCompleted, runs[0].Status)
+	// Three batches for tracking events, one empty batch for each other class
+	assert.Equal(t, 6, repo.batches)
+	assert.Len(t, repo.runs, 4)
+}
+
+func TestEnforceRecordsFailedRun(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	repo.purgeErr = errors.New("boom")
+
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+
+	runs, err := svc.Enforce(context.Background(), uuid.New())
+	assert.Error(t, err)
+	require.Len(t, runs, 4)
+	for _, run := range runs {
+		assert.Equal(t, types.RetentionRunStatusFailed, run.Status)
+		require.NotNil(t, run.Error)
+	}
+}
+
+func TestPlaceLegalHoldRequiresReason(t *testing.T) {
+	repo := newFakeRetentionRepo()
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+
+	_, err := svc.PlaceLegalHold(context.Background(), uuid.New(), uuid.New(), types.LegalHoldCreateRequest{
+		EntityClass: types.EntityClassLostLeads,
+		Reason:      "   ",
+	})
+	assert.Error(t, err)
+	assert.Empty(t, repo.createdHs)
+
+	hold, err := svc.PlaceLegalHold(context.Background(), uuid.New(), uuid.New(), types.LegalHoldCreateRequest{
+		EntityClass: types.EntityClassLostLeads,
+		Reason:      " litigation ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "litigation", hold.Reason)
+}
+
+type fakeAuditTrail struct {
+	days int
+}
+
+func (f fakeAuditTrail) AuditRetentionDays(ctx context.Context, orgID uuid.UUID) (int, error) {
+	return f.days, nil
+}
+
+func TestEnforceKeepsAuditLogsForAuditTrailRetention(t *testing.T) {
+	orgID := uuid.New()
+	now := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	repo := newFakeRetentionRepo()
+	repo.policies = []types.RetentionPolicy{
+		{OrganizationID: orgID, EntityClass: types.EntityClassAuditLogs, RetentionDays: 400, Enabled: true},
+		{OrganizationID: orgID, EntityClass: types.EntityClassLostLeads, RetentionDays: 400, Enabled: true},
+	}
+
+	svc := NewRetentionService(repo, &fakeAuth{}, nil)
+	svc.now = func() time.Time { return now }
+	svc.SetAuditTrailRetention(fakeAuditTrail{days: 3650})
+
+	runs, err := svc.Enforce(context.Background(), orgID)
+	require.NoError(t, err)
+	require.Len(t, runs, 4)
+	assert.Equal(t, now.AddDate(0, 0, -3650), runs[1].Cutoff)
+	assert.Equal(t, now.AddDate(0, 0, -3650), repo.cutoffs[types.EntityClassAuditLogs])
+	assert.Equal(t, now.AddDate(0, 0, -400), repo.cutoffs[types.EntityClassLostLeads], "other classes keep their policy")
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EntityClass identifies a class of records governed by a retention policy
+type EntityClass string
+
+const (
+	EntityClassTrackingEvents EntityClass = "tracking_events"
+	EntityClassAuditLogs      EntityClass = "audit_logs"
+	EntityClassLostLeads      EntityClass = "lost_leads"
+	EntityClassDeletedLeads   EntityClass = "deleted_leads"
+)
+
+// AllEntityClasses lists every entity class that retention can be enforced on
+var AllEntityClasses = []EntityClass{
+	EntityClassTrackingEvents,
+	EntityClassAuditLogs,
+	EntityClassLostLeads,
+	EntityClassDeletedLeads,
+}
+
+// IsValid reports whether the entity class is known
+func (c EntityClass) IsValid() bool {
+	for _, known := range AllEntityClasses {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRetentionDays is the retention applied when an organization has not configured a policy
+var DefaultRetentionDays = map[EntityClass]int{
+	EntityClassTrackingEvents: 90,
+	EntityClassAuditLogs:      7 * 365,
+	EntityClassLostLeads:      3 * 365,
+	EntityClassDeletedLeads:   30,
+}
+
+// MinRetentionDays is the shortest retention an organization may configure per class
+var MinRetentionDays = map[EntityClass]int{
+	EntityClassTrackingEvents: 7,
+	EntityClassAuditLogs:      365,
+	EntityClassLostLeads:      30,
+	EntityClassDeletedLeads:   1,
+}
+
+// RetentionPolicy is the retention configured for one entity class in an organization
+type RetentionPolicy struct {
+	ID             uuid.UUID   `json:"id" db:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id" db:"organization_id"`
+	EntityClass    EntityClass `json:"entity_class" db:"entity_class"`
+	RetentionDays  int         `json:"retention_days" db:"retention_days"`
+	Enabled        bool        `json:"enabled" db:"enabled"`
+	IsDefault      bool        `json:"is_default" db:"-"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+	UpdatedBy      *uuid.UUID  `json:"updated_by,omitempty" db:"updated_by"`
+}
+
+// Cutoff returns the instant before which records fall outside the policy
+func (p RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.RetentionDays)
+}
+
+// RetentionPolicyUpdateRequest configures the retention for an entity class
+type RetentionPolicyUpdateRequest struct {
+	RetentionDays int   `json:"retention_days"`
+	Enabled       *bool `json:"enabled,omitempty"`
+}
+
+// LegalHold exempts records from retention enforcement.
+// A hold without a RecordID covers the whole entity class.
+type LegalHold struct {
+	ID             uuid.UUID   `json:"id" db:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id" db:"organization_id"`
+	EntityClass    EntityClass `json:"entity_class" db:"entity_class"`
+	RecordID       *uuid.UUID  `json:"record_id,omitempty" db:"record_id"`
+	Reason         string      `json:"reason" db:"reason"`
+	PlacedBy       uuid.UUID   `json:"placed_by" db:"placed_by"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	ReleasedAt     *time.Time  `json:"released_at,omitempty" db:"released_at"`
+	ReleasedBy     *uuid.UUID  `json:"released_by,omitempty" db:"released_by"`
+}
+
+// LegalHoldCreateRequest places a legal hold
+type LegalHoldCreateRequest struct {
+	EntityClass EntityClass `json:"entity_class"`
+	RecordID    *uuid.UUID  `json:"record_id,omitempty"`
+	Reason      string      `json:"reason"`
+}
+
+// PurgePreview describes what the next enforcement run will purge for one entity class
+type PurgePreview struct {
+	EntityClass   EntityClass `json:"entity_class"`
+	RetentionDays int         `json:"retention_days"`
+	Enabled       bool        `json:"enabled"`
+	Cutoff        time.Time   `json:"cutoff"`
+	PurgeCount    int         `json:"purge_count"`
+	HeldCount     int         `json:"held_count"`
+	ClassHeld     bool        `json:"class_held"`
+}
+
+// PurgeReport is the preview across all entity classes for an organization
+type PurgeReport struct {
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	NextRunAt      time.Time      `json:"next_run_at"`
+	Classes        []PurgePreview `json:"classes"`
+}
+
+// RetentionRunStatus represents the outcome of an enforcement run
+type RetentionRunStatus string
+
+const (
+	RetentionRunStatusCompleted RetentionRunStatus = "completed"
+	RetentionRunStatusSkipped   RetentionRunStatus = "skipped"
+	RetentionRunStatusFailed    RetentionRunStatus = "failed"
+)
+
+// RetentionRun records one enforcement of a policy
+type RetentionRun struct {
+	ID             uuid.UUID          `json:"id" db:"id"`
+	OrganizationID uuid.UUID          `json:"organization_id" db:"organization_id"`
+	EntityClass    EntityClass        `json:"entity_class" db:"entity_class"`
+	Cutoff         time.Time          `json:"cutoff" db:"cutoff"`
+	PurgedCount    int                `json:"purged_count" db:"purged_count"`
+	HeldCount      int                `json:"held_count" db:"held_count"`
+	Status         RetentionRunStatus `json:"status" db:"status"`
+	Error          *string            `json:"error,omitempty" db:"error"`
+	StartedAt      time.Time          `json:"started_at" db:"started_at"`
+	FinishedAt     time.Time          `json:"finished_at" db:"finished_at"`
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type DropshipHandler struct {
+	service *service.DropshipService
+}
+
+func NewDropshipHandler(service *service.DropshipService) *DropshipHandler {
+	return &DropshipHandler{
+		service: service,
+	}
+}
+
+func (h *DropshipHandler) RegisterRoutes(router *httprouter.Router) {
+	router.PUT("/api/sales/orders/:id/lines/:line_id/route", h.SetLineRoute)
+	router.POST("/api/sales/orders/:id/dropships", h.CreatePurchaseOrders)
+	router.GET("/api/sales/orders/:id/dropships", h.ListDropships)
+	router.GET("/api/sales/dropships/:id", h.GetDropship)
+	router.POST("/api/sales/dropships/:id/tracking", h.RecordTracking)
+}
+
+// writeDropshipError maps dropship errors to HTTP statuses
+func writeDropshipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDropship):
+		respondError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrLineAlreadyOrdered):
+		respondError(w, err.Error(), http.StatusConflict)
+	default:
+		respondError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SetLineRoute handles PUT /api/sales/orders/:id/lines/:line_id/route
+func (h *DropshipHandler) SetLineRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	lineID, err := uuid.Parse(ps.ByName("line_id"))
+	if err != nil {
+		respondError(w, "Invalid line ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.SalesLineRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	routing, err := h.service.SetLineRoute(r.Context(), authCtx.OrganizationID, orderID, lineID, request)
+	if err != nil {
+		writeDropshipError(w, err)
+		return
+	}
+	if routing == nil {
+		respondError(w, "Sales order line or vendor not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, routing, http.StatusOK)
+}
+
+// CreatePurchaseOrders handles POST /api/sales/orders/:id/dropships, ordering
+// the order's pending dropship lines from their vendors
+func (h *DropshipHandler) CreatePurchaseOrders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	dropships, err := h.service.CreatePurchaseOrders(r.Context(), authCtx.OrganizationID, authCtx.UserID, orderID)
+	if err != nil {
+		writeDropshipError(w, err)
+		return
+	}
+	if dropships == nil {
+		respondError(w, "Sales order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, dropships, http.StatusCreated)
+}
+
+// ListDropships handles GET /api/sales/orders/:id/dropships
+func (h *DropshipHandler) ListDropships(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	dropships, err := h.service.ListDropships(r.Context(), authCtx.OrganizationID, orderID)
+	if err != nil {
+		writeDropshipError(w, err)
+		return
+	}
+	respondJSON(w, dropships, http.StatusOK)
+}
+
+// GetDropship handles GET /api/sales/dropships/:id
+func (h *DropshipHandler) GetDropship(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid dropship ID", http.StatusBadRequest)
+		return
+	}
+
+	dropship, err := h.service.GetDropship(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeDropshipError(w, err)
+		return
+	}
+	if dropship == nil {
+		respondError(w, "Dropship order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, dropship, http.StatusOK)
+}
+
+// RecordTracking handles POST /api/sales/dropships/:id/tracking with the
+// tracking data reported by the vendor
+func (h *DropshipHandler) RecordTracking(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid dropship ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.DropshipTrackingRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dropship, err := h.service.RecordTracking(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, request)
+	if err != nil {
+		writeDropshipError(w, err)
+		return
+	}
+	if dropship == nil {
+		respondError(w, "Dropship order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, dropship, http.StatusOK)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type IntercompanyHandler struct {
+	service *service.IntercompanyService
+}
+
+func NewIntercompanyHandler(service *service.IntercompanyService) *IntercompanyHandler {
+	return &IntercompanyHandler{
+		service: service,
+	}
+}
+
+func (h *IntercompanyHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/sales/intercompany/partners", h.ListPartners)
+	router.PUT("/api/sales/intercompany/partners", h.SavePartner)
+	router.POST("/api/sales/orders/:id/intercompany-invoice", h.InvoiceOrder)
+	router.GET("/api/sales/orders/:id/intercompany-invoice", h.GetInvoice)
+}
+
+// writeIntercompanyError maps intercompany errors to HTTP statuses
+func writeIntercompanyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidIntercompany):
+		respondError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAlreadyInvoiced), errors.Is(err, service.ErrPartnerInUse):
+		respondError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrNoIntercompanyJournal):
+		respondError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		respondError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListPartners handles GET /api/sales/intercompany/partners
+func (h *IntercompanyHandler) ListPartners(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	partners, err := h.service.ListPartners(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeIntercompanyError(w, err)
+		return
+	}
+	respondJSON(w, partners, http.StatusOK)
+}
+
+// SavePartner handles PUT /api/sales/intercompany/partners
+func (h *IntercompanyHandler) SavePartner(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.IntercompanyPartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	partner, err := h.service.SavePartner(r.Context(), authCtx.OrganizationID, request)
+	if err != nil {
+		writeIntercompanyError(w, err)
+		return
+	}
+	if partner == nil {
+		respondError(w, "Company or contact not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, partner, http.StatusOK)
+}
+
+// InvoiceOrder handles POST /api/sales/orders/:id/intercompany-invoice
+func (h *IntercompanyHandler) InvoiceOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.service.InvoiceOrder(r.Context(), authCtx.OrganizationID, authCtx.UserID, orderID)
+	if err != nil {
+		writeIntercompanyError(w, err)
+		return
+	}
+	if invoice == nil {
+		respondError(w, "Sales order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, invoice, http.StatusCreated)
+}
+
+// GetInvoice handles GET /api/sales/orders/:id/intercompany-invoice
+func (h *IntercompanyHandler) GetInvoice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.service.GetInvoice(r.Context(), authCtx.OrganizationID, orderID)
+	if err != nil {
+		writeIntercompanyError(w, err)
+		return
+	}
+	if invoice == nil {
+		respondError(w, "Intercompany invoice not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, invoice, http.StatusOK)
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type MarginHandler struct {
+	service *service.MarginService
+}
+
+func NewMarginHandler(service *service.MarginService) *MarginHandler {
+	return &MarginHandler{
+		service: service,
+	}
+}
+
+func (h *MarginHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/sales/orders/:id/margin", h.GetQuoteMargin)
+	router.POST("/api/sales/orders/:id/margin-approval", h.RequestApproval)
+	router.GET("/api/sales/margin-approvals", h.ListApprovals)
+	router.GET("/api/sales/margin-approvals/:id", h.GetApproval)
+	router.POST("/api/sales/margin-approvals/:id/approve", h.ApproveApproval)
+	router.POST("/api/sales/margin-approvals/:id/reject", h.RejectApproval)
+	router.GET("/api/sales/margin-settings", h.GetSettings)
+	router.PUT("/api/sales/margin-settings", h.UpdateSettings)
+	router.GET("/api/sales/margin-analytics", h.GetAnalytics)
+}
+
+// writeMarginError maps margin errors to HTTP statuses
+func writeMarginError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMargin):
+		respondError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrMarginApprovalPending), errors.Is(err, service.ErrMarginApprovalNotPending):
+		respondError(w, err.Error(), http.StatusConflict)
+	default:
+		respondError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetQuoteMargin handles GET /api/sales/orders/:id/margin
+func (h *MarginHandler) GetQuoteMargin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	margin, err := h.service.GetQuoteMargin(r.Context(), authCtx.OrganizationID, orderID)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	if margin == nil {
+		respondError(w, "Sales order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, margin, http.StatusOK)
+}
+
+// RequestApproval handles POST /api/sales/orders/:id/margin-approval,
+// routing a quote below the minimum margins for approval
+func (h *MarginHandler) RequestApproval(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.MarginApprovalRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	approval, err := h.service.RequestApproval(r.Context(), authCtx.OrganizationID, authCtx.UserID, orderID, request)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	if approval == nil {
+		respondError(w, "Sales order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, approval, http.StatusCreated)
+}
+
+// ListApprovals handles GET /api/sales/margin-approvals with optional
+// status, order_id, limit and offset parameters
+func (h *MarginHandler) ListApprovals(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.MarginApprovalFilter{OrganizationID: authCtx.OrganizationID}
+	if v := query.Get("status"); v != "" {
+		status := types.MarginApprovalStatus(v)
+		filter.Status = &status
+	}
+	if v := query.Get("order_id"); v != "" {
+		orderID, err := uuid.Parse(v)
+		if err != nil {
+			respondError(w, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+		filter.OrderID = &orderID
+	}
+	for name, dest := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				respondError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = n
+		}
+	}
+
+	approvals, err := h.service.ListApprovals(r.Context(), filter)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	respondJSON(w, approvals, http.StatusOK)
+}
+
+// GetApproval handles GET /api/sales/margin-approvals/:id
+func (h *MarginHandler) GetApproval(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid margin approval ID", http.StatusBadRequest)
+		return
+	}
+
+	approval, err := h.service.GetApproval(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	if approval == nil {
+		respondError(w, "Margin approval not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, approval, http.StatusOK)
+}
+
+// ApproveApproval handles POST /api/sales/margin-approvals/:id/approve
+func (h *MarginHandler) ApproveApproval(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.review(w, r, ps, h.service.ApproveApproval)
+}
+
+// RejectApproval handles POST /api/sales/margin-approvals/:id/reject
+func (h *MarginHandler) RejectApproval(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.review(w, r, ps, h.service.RejectApproval)
+}
+
+func (h *MarginHandler) review(w http.ResponseWriter, r *http.Request, ps httprouter.Params,
+	review func(ctx context.Context, orgID, id, userID uuid.UUID, review types.MarginApprovalReview) (*types.MarginApproval, error)) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid margin approval ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.MarginApprovalReview
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	approval, err := review(r.Context(), authCtx.OrganizationID, id, authCtx.UserID, request)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	if approval == nil {
+		respondError(w, "Margin approval not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, approval, http.StatusOK)
+}
+
+// GetSettings handles GET /api/sales/margin-settings
+func (h *MarginHandler) GetSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	respondJSON(w, settings, http.StatusOK)
+}
+
+// UpdateSettings handles PUT /api/sales/margin-settings
+func (h *MarginHandler) UpdateSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var settings types.MarginSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateSettings(r.Context(), authCtx.OrganizationID, authCtx.UserID, settings)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	respondJSON(w, updated, http.StatusOK)
+}
+
+// GetAnalytics handles GET /api/sales/margin-analytics with group_by rep or
+// product and optional RFC 3339 from and to bounds on the order date
+func (h *MarginHandler) GetAnalytics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	filter := types.MarginAnalyticsFilter{
+		OrganizationID: authCtx.OrganizationID,
+		GroupBy:        types.MarginAnalyticsGroup(r.URL.Query().Get("group_by")),
+	}
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondError(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	report, err := h.service.Analytics(r.Context(), filter)
+	if err != nil {
+		writeMarginError(w, err)
+		return
+	}
+	respondJSON(w, report, http.StatusOK)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type PromotionHandler struct {
+	service *service.PromotionService
+}
+
+func NewPromotionHandler(service *service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		service: service,
+	}
+}
+
+func (h *PromotionHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/sales/promotions", h.ListPromotions)
+	router.POST("/api/sales/promotions", h.CreatePromotion)
+	router.GET("/api/sales/promotions/:id", h.GetPromotion)
+	router.PUT("/api/sales/promotions/:id", h.UpdatePromotion)
+	router.GET("/api/sales/promotions/:id/performance", h.GetPromotionPerformance)
+	router.GET("/api/sales/promotion-performance", h.ListPerformance)
+	router.POST("/api/sales/orders/:id/price", h.PriceOrder)
+	router.GET("/api/sales/orders/:id/promotions", h.ListOrderPromotions)
+}
+
+// writePromotionError maps promotion errors to HTTP statuses
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPromotion):
+		respondError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCouponInUse):
+		respondError(w, err.Error(), http.StatusConflict)
+	default:
+		respondError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListPromotions handles GET /api/sales/promotions
+func (h *PromotionHandler) ListPromotions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	promotions, err := h.service.ListPromotions(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	respondJSON(w, promotions, http.StatusOK)
+}
+
+// CreatePromotion handles POST /api/sales/promotions
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var request types.PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.service.CreatePromotion(r.Context(), authCtx.OrganizationID, authCtx.UserID, request)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	respondJSON(w, promotion, http.StatusCreated)
+}
+
+// GetPromotion handles GET /api/sales/promotions/:id
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.service.GetPromotion(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	if promotion == nil {
+		respondError(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, promotion, http.StatusOK)
+}
+
+// UpdatePromotion handles PUT /api/sales/promotions/:id
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.service.UpdatePromotion(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, request)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	if promotion == nil {
+		respondError(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, promotion, http.StatusOK)
+}
+
+// ListPerformance handles GET /api/sales/promotion-performance with optional
+// RFC 3339 from and to bounds on the order date
+func (h *PromotionHandler) ListPerformance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.performance(w, r, nil)
+}
+
+// GetPromotionPerformance handles GET /api/sales/promotions/:id/performance
+// with optional RFC 3339 from and to bounds on the order date
+func (h *PromotionHandler) GetPromotionPerformance(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+	h.performance(w, r, &id)
+}
+
+func (h *PromotionHandler) performance(w http.ResponseWriter, r *http.Request, promotionID *uuid.UUID) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var bounds [2]*time.Time
+	for i, name := range []string{"from", "to"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondError(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			bounds[i] = &t
+		}
+	}
+
+	report, err := h.service.Performance(r.Context(), authCtx.OrganizationID, promotionID, bounds[0], bounds[1])
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	if promotionID != nil {
+		if len(report) == 0 {
+			respondError(w, "Promotion not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, report[0], http.StatusOK)
+		return
+	}
+	respondJSON(w, report, http.StatusOK)
+}
+
+// PriceOrder handles POST /api/sales/orders/:id/price, applying the eligible
+// promotions and the entered coupon codes to a quotation
+func (h *PromotionHandler) PriceOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var request types.PriceOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pricing, err := h.service.PriceOrder(r.Context(), authCtx.OrganizationID, authCtx.UserID, orderID, request)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	if pricing == nil {
+		respondError(w, "Sales order not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, pricing, http.StatusOK)
+}
+
+// ListOrderPromotions handles GET /api/sales/orders/:id/promotions
+func (h *PromotionHandler) ListOrderPromotions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		respondError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	applied, err := h.service.ListOrderPromotions(r.Context(), authCtx.OrganizationID, orderID)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+	respondJSON(w, applied, http.StatusOK)
+}
@@ -93,7 +93,7 @@ func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request, ps httpr
 		return
 	}
 
-	quote, err := h.quoteService.salesOrderService.GetSalesOrder(r.Context(), id)
+	quote, err := h.quoteService.GetQuote(r.Context(), id)
 	if err != nil {
 		respondError(w, err.Error(), http.StatusInternalServerError)
 		return
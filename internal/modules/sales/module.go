@@ -16,9 +16,13 @@ import (
 
 // SalesModule represents the Sales module
 type SalesModule struct {
-	salesOrderHandler *handler.SalesOrderHandler
-	pricelistHandler  *handler.PricelistHandler
-	logger            *slog.Logger
+	salesOrderHandler   *handler.SalesOrderHandler
+	pricelistHandler    *handler.PricelistHandler
+	dropshipHandler     *handler.DropshipHandler
+	intercompanyHandler *handler.IntercompanyHandler
+	promotionHandler    *handler.PromotionHandler
+	marginHandler       *handler.MarginHandler
+	logger              *slog.Logger
 }
 
 // NewSalesModule creates a new Sales module
@@ -40,6 +44,10 @@ func (m *SalesModule) Init(ctx context.Context, deps registry.Dependencies) erro
 	// Create repositories
 	salesOrderRepo := repository.NewSalesOrderRepository(deps.DB)
 	pricelistRepo := repository.NewPricelistRepository(deps.DB)
+	dropshipRepo := repository.NewDropshipRepository(deps.DB)
+	intercompanyRepo := repository.NewIntercompanyRepository(deps.DB)
+	promotionRepo := repository.NewPromotionRepository(deps.DB)
+	marginRepo := repository.NewMarginRepository(deps.DB)
 
 	// Create tax calculator
 	taxCalc := tax.NewCalculator(deps.DB)
@@ -47,10 +55,18 @@ func (m *SalesModule) Init(ctx context.Context, deps registry.Dependencies) erro
 	// Create services with event bus support
 	salesOrderService := service.NewSalesOrderServiceWithEventBus(salesOrderRepo, pricelistRepo, taxCalc, deps.EventBus)
 	pricelistService := service.NewPricelistService(pricelistRepo)
+	dropshipService := service.NewDropshipService(dropshipRepo)
+	intercompanyService := service.NewIntercompanyService(intercompanyRepo)
+	promotionService := service.NewPromotionService(promotionRepo)
+	marginService := service.NewMarginServiceWithEventBus(marginRepo, deps.EventBus)
 
 	// Create handlers
 	m.salesOrderHandler = handler.NewSalesOrderHandler(salesOrderService)
 	m.pricelistHandler = handler.NewPricelistHandler(pricelistService)
+	m.dropshipHandler = handler.NewDropshipHandler(dropshipService)
+	m.intercompanyHandler = handler.NewIntercompanyHandler(intercompanyService)
+	m.promotionHandler = handler.NewPromotionHandler(promotionService)
+	m.marginHandler = handler.NewMarginHandler(marginService)
 
 	m.logger.Info("Sales module initialized successfully")
 	return nil
@@ -66,6 +82,18 @@ func (m *SalesModule) RegisterRoutes(router interface{}) {
 			if m.pricelistHandler != nil {
 				m.pricelistHandler.RegisterRoutes(r)
 			}
+			if m.dropshipHandler != nil {
+				m.dropshipHandler.RegisterRoutes(r)
+			}
+			if m.intercompanyHandler != nil {
+				m.intercompanyHandler.RegisterRoutes(r)
+			}
+			if m.promotionHandler != nil {
+				m.promotionHandler.RegisterRoutes(r)
+			}
+			if m.marginHandler != nil {
+				m.marginHandler.RegisterRoutes(r)
+			}
 		}
 	}
 }
@@ -0,0 +1,567 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrLineAlreadyOrdered is returned when a dropship line has already been
+// ordered from its vendor
+var ErrLineAlreadyOrdered = errors.New("sales order line has already been ordered from its vendor")
+
+type DropshipRepository interface {
+	SetLineRoute(ctx context.Context, orgID, orderID, lineID uuid.UUID, request types.SalesLineRouteRequest) (*types.SalesLineRouting, error)
+	FindSource(ctx context.Context, orgID, orderID uuid.UUID) (*types.DropshipSource, error)
+	CreateDropship(ctx context.Context, orgID, userID uuid.UUID, source types.DropshipSource, vendorID uuid.UUID, lines []types.DropshipLine) (*types.DropshipOrder, error)
+	FindDropship(ctx context.Context, orgID, id uuid.UUID) (*types.DropshipOrder, error)
+	ListDropships(ctx context.Context, orgID, orderID uuid.UUID) ([]types.DropshipOrder, error)
+	RecordTracking(ctx context.Context, orgID, userID uuid.UUID, dropship types.DropshipOrder, request types.DropshipTrackingRequest) error
+}
+
+type dropshipRepository struct {
+	db *sql.DB
+}
+
+func NewDropshipRepository(db *sql.DB) DropshipRepository {
+	return &dropshipRepository{db: db}
+}
+
+// SetLineRoute sets the route of a line that has not been ordered from a
+// vendor yet. It returns nil when the line or the vendor does not exist.
+func (r *dropshipRepository) SetLineRoute(ctx context.Context, orgID, orderID, lineID uuid.UUID, request types.SalesLineRouteRequest) (*types.SalesLineRouting, error) {
+	if request.VendorID != nil {
+		var exists bool
+		err := r.db.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM contacts WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+			)
+		`, orgID, *request.VendorID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find vendor: %w", err)
+		}
+		if !exists {
+			return nil, nil
+		}
+	}
+
+	var routing types.SalesLineRouting
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE sales_order_lines
+		SET route = $4, dropship_vendor_id = $5, dropship_cost = $6, updated_at = NOW()
+		WHERE organization_id = $1 AND order_id = $2 AND id = $3
+			AND deleted_at IS NULL AND purchase_line_id IS NULL
+		RETURNING id, order_id, route, dropship_vendor_id, dropship_cost, purchase_line_id
+	`, orgID, orderID, lineID, request.Route, request.VendorID, request.UnitCost).Scan(
+		&routing.LineID, &routing.OrderID, &routing.Route, &routing.VendorID,
+		&routing.UnitCost, &routing.PurchaseLineID,
+	)
+	if err == nil {
+		return &routing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to set sales order line route: %w", err)
+	}
+
+	var ordered bool
+	err = r.db.QueryRowContext(ctx, `
+		SELECT purchase_line_id IS NOT NULL
+		FROM sales_order_lines
+		WHERE organization_id = $1 AND order_id = $2 AND id = $3 AND deleted_at IS NULL
+	`, orgID, orderID, lineID).Scan(&ordered)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sales order line: %w", err)
+	}
+	if ordered {
+		return nil, ErrLineAlreadyOrdered
+	}
+	return nil, nil
+}
+
+// FindSource returns a sales order with its dropship lines that have not been
+// ordered yet, or nil when the order does not exist
+func (r *dropshipRepository) FindSource(ctx context.Context, orgID, orderID uuid.UUID) (*types.DropshipSource, error) {
+	var source types.DropshipSource
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(state, 'draft'), company_id, currency_id,
+			COALESCE(partner_shipping_id, partner_id)
+		FROM sales_orders
+		WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, orgID, orderID).Scan(
+		&source.OrderID, &source.OrderName, &source.State, &source.CompanyID,
+		&source.CurrencyID, &source.CustomerID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sales order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id, l.product_id, l.name, COALESCE(l.product_uom_qty, 0),
+			COALESCE(l.dropship_cost, p.standard_price, 0), l.dropship_vendor_id, l.purchase_line_id
+		FROM sales_order_lines l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.organization_id = $1 AND l.order_id = $2 AND l.route = 'dropship'
+			AND l.purchase_line_id IS NULL AND l.deleted_at IS NULL AND l.display_type IS NULL
+		ORDER BY l.sequence, l.created_at
+	`, orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dropship lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line types.DropshipLine
+		if err := rows.Scan(&line.SalesLineID, &line.ProductID, &line.Name, &line.Quantity,
+			&line.UnitCost, &line.VendorID, &line.PurchaseLineID); err != nil {
+			return nil, fmt.Errorf("failed to scan dropship line: %w", err)
+		}
+		source.Lines = append(source.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// CreateDropship drafts a purchase order to the vendor for the lines,
+// delivered to the order's shipping partner, and links the lines to it
+func (r *dropshipRepository) CreateDropship(ctx context.Context, orgID, userID uuid.UUID, source types.DropshipSource, vendorID uuid.UUID, lines []types.DropshipLine) (*types.DropshipOrder, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lineIDs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		lineIDs = append(lineIDs, line.SalesLineID.String())
+	}
+	var pending int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM sales_order_lines
+			WHERE organization_id = $1 AND order_id = $2 AND id = ANY($3::uuid[]) AND purchase_line_id IS NULL
+			FOR UPDATE
+		) pending
+	`, orgID, source.OrderID, pq.Array(lineIDs)).Scan(&pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock dropship lines: %w", err)
+	}
+	if pending != len(lines) {
+		return nil, ErrLineAlreadyOrdered
+	}
+
+	var existing int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sales_dropships WHERE organization_id = $1 AND sales_order_id = $2
+	`, orgID, source.OrderID).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("failed to count dropship orders: %w", err)
+	}
+
+	var total float64
+	for _, line := range lines {
+		total += line.Quantity * line.UnitCost
+	}
+
+	dropship := types.DropshipOrder{
+		OrganizationID:    orgID,
+		SalesOrderID:      source.OrderID,
+		SalesOrderName:    source.OrderName,
+		PurchaseOrderName: fmt.Sprintf("%s/DS%02d", source.OrderName, existing+1),
+		PurchaseState:     "draft",
+		VendorID:          vendorID,
+		CustomerID:        source.CustomerID,
+		AmountTotal:       total,
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"dropship":       true,
+		"sales_order_id": source.OrderID,
+	})
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO purchase_orders (
+			organization_id, company_id, name, state, partner_id, currency_id,
+			amount_untaxed, amount_total, dest_address_id, origin, user_id, created_by, metadata
+		) VALUES ($1, $2, $3, 'draft', $4, $5, $6, $6, $7, $8, $9, $9, $10)
+		RETURNING id
+	`, orgID, source.CompanyID, dropship.PurchaseOrderName, vendorID, source.CurrencyID,
+		total, source.CustomerID, source.OrderName, userID, metadata,
+	).Scan(&dropship.PurchaseOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dropship purchase order: %w", err)
+	}
+
+	for i, line := range lines {
+		var purchaseLineID uuid.UUID
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO purchase_order_lines (
+				organization_id, order_id, sequence, name, product_id, product_qty,
+				price_unit, price_subtotal, price_total, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
+			RETURNING id
+		`, orgID, dropship.PurchaseOrderID, (i+1)*10, line.Name, line.ProductID, line.Quantity,
+			line.UnitCost, line.Quantity*line.UnitCost, userID,
+		).Scan(&purchaseLineID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dropship purchase order line: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE sales_order_lines SET purchase_line_id = $2, updated_at = NOW() WHERE id = $1
+		`, line.SalesLineID, purchaseLineID); err != nil {
+			return nil, fmt.Errorf("failed to link dropship line: %w", err)
+		}
+		line.PurchaseLineID = &purchaseLineID
+		dropship.Lines = append(dropship.Lines, line)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO sales_dropships (
+			organization_id, sales_order_id, purchase_order_id, vendor_id, customer_id, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, orgID, source.OrderID, dropship.PurchaseOrderID, vendorID, source.CustomerID, userID,
+	).Scan(&dropship.ID, &dropship.CreatedAt, &dropship.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dropship order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit dropship order: %w", err)
+	}
+	return &dropship, nil
+}
+
+const dropshipSelect = `
+	SELECT d.id, d.organization_id, d.sales_order_id, so.name, d.purchase_order_id, po.name,
+		COALESCE(po.state, 'draft'), d.vendor_id, COALESCE(v.name, ''), d.customer_id,
+		COALESCE(po.amount_total, 0), d.picking_id, d.shipment_id, s.status, s.carrier_name,
+		s.tracking_number, d.tracking_url, d.created_at, d.updated_at
+	FROM sales_dropships d
+	JOIN sales_orders so ON so.id = d.sales_order_id
+	JOIN purchase_orders po ON po.id = d.purchase_order_id
+	LEFT JOIN contacts v ON v.id = d.vendor_id
+	LEFT JOIN delivery_shipments s ON s.id = d.shipment_id
+`
+
+func scanDropship(scanner interface{ Scan(...interface{}) error }) (*types.DropshipOrder, error) {
+	var d types.DropshipOrder
+	err := scanner.Scan(
+		&d.ID, &d.OrganizationID, &d.SalesOrderID, &d.SalesOrderName, &d.PurchaseOrderID,
+		&d.PurchaseOrderName, &d.PurchaseState, &d.VendorID, &d.VendorName, &d.CustomerID,
+		&d.AmountTotal, &d.PickingID, &d.ShipmentID, &d.ShipmentStatus, &d.CarrierName,
+		&d.TrackingNumber, &d.TrackingURL, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// findDropshipLines returns the sales order lines ordered on a dropship order
+func (r *dropshipRepository) findDropshipLines(ctx context.Context, purchaseOrderID uuid.UUID) ([]types.DropshipLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id, l.product_id, l.name, COALESCE(l.product_uom_qty, 0),
+			COALESCE(pol.price_unit, 0), l.dropship_vendor_id, l.purchase_line_id
+		FROM sales_order_lines l
+		JOIN purchase_order_lines pol ON pol.id = l.purchase_line_id
+		WHERE pol.order_id = $1
+		ORDER BY pol.sequence
+	`, purchaseOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dropship order lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []types.DropshipLine{}
+	for rows.Next() {
+		var line types.DropshipLine
+		if err := rows.Scan(&line.SalesLineID, &line.ProductID, &line.Name, &line.Quantity,
+			&line.UnitCost, &line.VendorID, &line.PurchaseLineID); err != nil {
+			return nil, fmt.Errorf("failed to scan dropship order line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// FindDropship returns a dropship order, or nil when it does not exist
+func (r *dropshipRepository) FindDropship(ctx context.Context, orgID, id uuid.UUID) (*types.DropshipOrder, error) {
+	dropship, err := scanDropship(r.db.QueryRowContext(ctx,
+		dropshipSelect+` WHERE d.organization_id = $1 AND d.id = $2`, orgID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dropship order: %w", err)
+	}
+
+	if dropship.Lines, err = r.findDropshipLines(ctx, dropship.PurchaseOrderID); err != nil {
+		return nil, err
+	}
+	return dropship, nil
+}
+
+// ListDropships lists the dropship orders of a sales order
+func (r *dropshipRepository) ListDropships(ctx context.Context, orgID, orderID uuid.UUID) ([]types.DropshipOrder, error) {
+	rows, err := r.db.QueryContext(ctx,
+		dropshipSelect+` WHERE d.organization_id = $1 AND d.sales_order_id = $2 ORDER BY d.created_at`,
+		orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dropship orders: %w", err)
+	}
+	var dropships []types.DropshipOrder
+	for rows.Next() {
+		dropship, err := scanDropship(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan dropship order: %w", err)
+		}
+		dropships = append(dropships, *dropship)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range dropships {
+		if dropships[i].Lines, err = r.findDropshipLines(ctx, dropships[i].PurchaseOrderID); err != nil {
+			return nil, err
+		}
+	}
+	return dropships, nil
+}
+
+// RecordTracking stores the vendor's tracking data on the dropship order's
+// delivery shipment, creating the shipment and its supplier-to-customer
+// picking on the first update. A delivered shipment completes the picking
+// and marks the lines delivered and received.
+func (r *dropshipRepository) RecordTracking(ctx context.Context, orgID, userID uuid.UUID, dropship types.DropshipOrder, request types.DropshipTrackingRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var companyID, shipmentID, pickingID *uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT so.company_id, d.shipment_id, d.picking_id
+		FROM sales_dropships d
+		JOIN sales_orders so ON so.id = d.sales_order_id
+		WHERE d.organization_id = $1 AND d.id = $2
+		FOR UPDATE OF d
+	`, orgID, dropship.ID).Scan(&companyID, &shipmentID, &pickingID)
+	if err != nil {
+		return fmt.Errorf("failed to lock dropship order: %w", err)
+	}
+
+	eventTime := time.Now()
+	if request.EventTime != nil {
+		eventTime = *request.EventTime
+	}
+
+	if shipmentID == nil {
+		if pickingID == nil {
+			id, err := r.createDropshipPicking(ctx, tx, orgID, userID, companyID, dropship)
+			if err != nil {
+				return err
+			}
+			pickingID = &id
+		}
+
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"dropship_id":       dropship.ID,
+			"purchase_order_id": dropship.PurchaseOrderID,
+		})
+		var id uuid.UUID
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO delivery_shipments (
+				organization_id, company_id, picking_id, tracking_number, carrier_name, carrier_code,
+				shipment_type, status, last_event_at, metadata, created_by, updated_by
+			) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''),
+				'outbound', COALESCE(NULLIF($7, ''), 'scheduled'), $8, $9, $10, $10)
+			RETURNING id
+		`, orgID, companyID, *pickingID, request.TrackingNumber, request.CarrierName, request.CarrierCode,
+			request.Status, eventTime, metadata, userID,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create dropship shipment: %w", err)
+		}
+		shipmentID = &id
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE delivery_shipments
+			SET tracking_number = COALESCE(NULLIF($3, ''), tracking_number),
+				carrier_name = COALESCE(NULLIF($4, ''), carrier_name),
+				carrier_code = COALESCE(NULLIF($5, ''), carrier_code),
+				status = COALESCE(NULLIF($6, ''), status),
+				last_event_at = $7, updated_at = NOW(), updated_by = $8
+			WHERE organization_id = $1 AND id = $2
+		`, orgID, *shipmentID, request.TrackingNumber, request.CarrierName, request.CarrierCode,
+			request.Status, eventTime, userID)
+		if err != nil {
+			return fmt.Errorf("failed to update dropship shipment: %w", err)
+		}
+	}
+
+	switch request.Status {
+	case "in_transit":
+		_, err = tx.ExecContext(ctx, `
+			UPDATE delivery_shipments SET departed_at = COALESCE(departed_at, $2) WHERE id = $1
+		`, *shipmentID, eventTime)
+	case "delivered":
+		_, err = tx.ExecContext(ctx, `
+			UPDATE delivery_shipments
+			SET departed_at = COALESCE(departed_at, $2), arrived_at = COALESCE(arrived_at, $2)
+			WHERE id = $1
+		`, *shipmentID, eventTime)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update dropship shipment times: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sales_dropships
+		SET picking_id = $2, shipment_id = $3, tracking_url = COALESCE(NULLIF($4, ''), tracking_url), updated_at = NOW()
+		WHERE id = $1
+	`, dropship.ID, pickingID, *shipmentID, request.TrackingURL)
+	if err != nil {
+		return fmt.Errorf("failed to link dropship shipment: %w", err)
+	}
+
+	payload, _ := json.Marshal(request)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO delivery_tracking_events (
+			organization_id, shipment_id, event_type, status, event_time, source, message, raw_payload, created_by
+		) VALUES ($1, $2, 'vendor_update', NULLIF($3, ''), $4, 'vendor', NULLIF($5, ''), $6, $7)
+	`, orgID, *shipmentID, request.Status, eventTime, request.Message, payload, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record dropship tracking event: %w", err)
+	}
+
+	if request.Status == "delivered" && pickingID != nil {
+		if err := completeDropship(ctx, tx, *pickingID, dropship.PurchaseOrderID, eventTime); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dropship tracking: %w", err)
+	}
+	return nil
+}
+
+// createDropshipPicking creates the picking that moves a dropship order's
+// products from the vendor to the customer without passing through stock
+func (r *dropshipRepository) createDropshipPicking(ctx context.Context, tx *sql.Tx, orgID, userID uuid.UUID, companyID *uuid.UUID, dropship types.DropshipOrder) (uuid.UUID, error) {
+	supplierID, err := partnerLocation(ctx, tx, orgID, "supplier", "Partner Locations/Vendors")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	customerID, err := partnerLocation(ctx, tx, orgID, "customer", "Partner Locations/Customers")
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var pickingID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_pickings (
+			organization_id, company_id, name, location_id, location_dest_id, partner_id,
+			origin, state, scheduled_date, user_id, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, 'assigned', NOW(), $8, $8)
+		RETURNING id
+	`, orgID, companyID, dropship.PurchaseOrderName, supplierID, customerID, dropship.CustomerID,
+		dropship.SalesOrderName, userID,
+	).Scan(&pickingID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create dropship picking: %w", err)
+	}
+
+	for _, line := range dropship.Lines {
+		if line.ProductID == nil {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO stock_moves (
+				organization_id, company_id, name, product_id, product_uom_qty, location_id,
+				location_dest_id, partner_id, picking_id, state, origin, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'assigned', $10, $11)
+		`, orgID, companyID, line.Name, *line.ProductID, line.Quantity, supplierID, customerID,
+			dropship.CustomerID, pickingID, dropship.PurchaseOrderName, userID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create dropship move: %w", err)
+		}
+	}
+	return pickingID, nil
+}
+
+// completeDropship marks a delivered dropship picking done, the purchase
+// order lines received and the sales order lines delivered
+func completeDropship(ctx context.Context, tx *sql.Tx, pickingID, purchaseOrderID uuid.UUID, doneAt time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE stock_pickings SET state = 'done', date_done = $2, updated_at = NOW()
+		WHERE id = $1 AND state <> 'done'
+	`, pickingID, doneAt); err != nil {
+		return fmt.Errorf("failed to complete dropship picking: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE stock_moves SET state = 'done', updated_at = NOW() WHERE picking_id = $1 AND state <> 'done'
+	`, pickingID); err != nil {
+		return fmt.Errorf("failed to complete dropship moves: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE purchase_order_lines SET qty_received = product_qty, updated_at = NOW() WHERE order_id = $1
+	`, purchaseOrderID); err != nil {
+		return fmt.Errorf("failed to receive dropship purchase order: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sales_order_lines l
+		SET qty_delivered = l.product_uom_qty, updated_at = NOW()
+		FROM purchase_order_lines pol
+		WHERE pol.order_id = $1 AND l.purchase_line_id = pol.id
+	`, purchaseOrderID); err != nil {
+		return fmt.Errorf("failed to deliver dropship lines: %w", err)
+	}
+	return nil
+}
+
+// partnerLocation returns the organization's first active location of a
+// partner usage, supplier or customer, creating it on first use
+func partnerLocation(ctx context.Context, tx *sql.Tx, orgID uuid.UUID, usage, completeName string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM stock_locations
+		WHERE organization_id = $1 AND usage = $2 AND active = true AND deleted_at IS NULL
+		ORDER BY created_at
+		LIMIT 1
+	`, orgID, usage).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to find %s location: %w", usage, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO stock_locations (organization_id, name, complete_name, usage)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, orgID, path.Base(completeName), completeName, usage).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create %s location: %w", usage, err)
+	}
+	return id, nil
+}
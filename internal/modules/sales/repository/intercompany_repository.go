@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrAlreadyInvoiced is returned when an intercompany sale already has its invoices
+	ErrAlreadyInvoiced = errors.New("intercompany sale has already been invoiced")
+
+	// ErrPartnerInUse is returned when a contact already represents another company
+	ErrPartnerInUse = errors.New("contact already represents another company")
+
+	// ErrNoIntercompanyJournal is returned when a company has no journal to
+	// book its side of an intercompany sale in
+	ErrNoIntercompanyJournal = errors.New("no journal with a default account for intercompany invoicing")
+)
+
+type IntercompanyRepository interface {
+	ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.IntercompanyPartner, error)
+	SavePartner(ctx context.Context, orgID uuid.UUID, request types.IntercompanyPartnerRequest) (*types.IntercompanyPartner, error)
+	FindTrade(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyTrade, error)
+	FindInvoice(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyInvoice, error)
+	CreateInvoices(ctx context.Context, orgID, userID uuid.UUID, trade types.IntercompanyTrade) (*types.IntercompanyInvoice, error)
+}
+
+type intercompanyRepository struct {
+	db *sql.DB
+}
+
+func NewIntercompanyRepository(db *sql.DB) IntercompanyRepository {
+	return &intercompanyRepository{db: db}
+}
+
+const intercompanyPartnerSelect = `
+	SELECT ip.company_id, c.name, ip.partner_id, COALESCE(p.name, ''), ip.updated_at
+	FROM intercompany_partners ip
+	JOIN companies c ON c.id = ip.company_id
+	LEFT JOIN contacts p ON p.id = ip.partner_id
+`
+
+// ListPartners lists the contacts the organization's companies trade under
+func (r *intercompanyRepository) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.IntercompanyPartner, error) {
+	rows, err := r.db.QueryContext(ctx, intercompanyPartnerSelect+` WHERE ip.organization_id = $1 ORDER BY c.name`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list intercompany partners: %w", err)
+	}
+	defer rows.Close()
+
+	var partners []types.IntercompanyPartner
+	for rows.Next() {
+		var p types.IntercompanyPartner
+		if err := rows.Scan(&p.CompanyID, &p.CompanyName, &p.PartnerID, &p.PartnerName, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan intercompany partner: %w", err)
+		}
+		partners = append(partners, p)
+	}
+	return partners, rows.Err()
+}
+
+// SavePartner links a company to the contact it trades under. It returns nil
+// when the company or the contact does not belong to the organization.
+func (r *intercompanyRepository) SavePartner(ctx context.Context, orgID uuid.UUID, request types.IntercompanyPartnerRequest) (*types.IntercompanyPartner, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO intercompany_partners (company_id, organization_id, partner_id)
+		SELECT c.id, c.organization_id, p.id
+		FROM companies c
+		JOIN contacts p ON p.organization_id = c.organization_id AND p.id = $3 AND p.deleted_at IS NULL
+		WHERE c.organization_id = $1 AND c.id = $2
+		ON CONFLICT (company_id) DO UPDATE
+		SET partner_id = EXCLUDED.partner_id, updated_at = NOW()
+	`, orgID, request.CompanyID, request.PartnerID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrPartnerInUse
+		}
+		return nil, fmt.Errorf("failed to save intercompany partner: %w", err)
+	}
+	if saved, err := result.RowsAffected(); err != nil || saved == 0 {
+		return nil, err
+	}
+
+	var p types.IntercompanyPartner
+	err = r.db.QueryRowContext(ctx, intercompanyPartnerSelect+` WHERE ip.organization_id = $1 AND ip.company_id = $2`,
+		orgID, request.CompanyID).Scan(&p.CompanyID, &p.CompanyName, &p.PartnerID, &p.PartnerName, &p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find intercompany partner: %w", err)
+	}
+	return &p, nil
+}
+
+// FindTrade returns a sales order with the companies on both sides of it, or
+// nil when the order does not exist
+func (r *intercompanyRepository) FindTrade(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyTrade, error) {
+	var trade types.IntercompanyTrade
+	err := r.db.QueryRowContext(ctx, `
+		SELECT o.id, o.name, COALESCE(o.state, 'draft'), o.company_id, seller.partner_id,
+			buyer.company_id, o.partner_id, o.currency_id, COALESCE(o.amount_untaxed, 0),
+			COALESCE(o.amount_tax, 0), COALESCE(o.amount_total, 0)
+		FROM sales_orders o
+		LEFT JOIN intercompany_partners seller
+			ON seller.organization_id = o.organization_id AND seller.company_id = o.company_id
+		LEFT JOIN intercompany_partners buyer
+			ON buyer.organization_id = o.organization_id AND buyer.partner_id = o.partner_id
+		WHERE o.organization_id = $1 AND o.id = $2 AND o.deleted_at IS NULL
+	`, orgID, orderID).Scan(
+		&trade.OrderID, &trade.OrderName, &trade.State, &trade.SellerCompanyID, &trade.SellerPartnerID,
+		&trade.BuyerCompanyID, &trade.BuyerPartnerID, &trade.CurrencyID, &trade.AmountUntaxed,
+		&trade.AmountTax, &trade.AmountTotal,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sales order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT product_id, name, COALESCE(product_uom_qty, 0), COALESCE(price_unit, 0),
+			COALESCE(price_subtotal, 0), COALESCE(price_total, 0)
+		FROM sales_order_lines
+		WHERE organization_id = $1 AND order_id = $2 AND deleted_at IS NULL AND display_type IS NULL
+		ORDER BY sequence, created_at
+	`, orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sales order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line types.IntercompanyLine
+		if err := rows.Scan(&line.ProductID, &line.Name, &line.Quantity, &line.UnitPrice,
+			&line.Subtotal, &line.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan sales order line: %w", err)
+		}
+		trade.Lines = append(trade.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// FindInvoice returns the intercompany invoices of a sales order, or nil
+// when it has none
+func (r *intercompanyRepository) FindInvoice(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyInvoice, error) {
+	var invoice types.IntercompanyInvoice
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, organization_id, sales_order_id, seller_company_id, buyer_company_id,
+			customer_invoice_id, vendor_bill_id, amount_total, created_at, created_by
+		FROM intercompany_invoices
+		WHERE organization_id = $1 AND sales_order_id = $2
+	`, orgID, orderID).Scan(
+		&invoice.ID, &invoice.OrganizationID, &invoice.SalesOrderID, &invoice.SellerCompanyID,
+		&invoice.BuyerCompanyID, &invoice.CustomerInvoiceID, &invoice.VendorBillID,
+		&invoice.AmountTotal, &invoice.CreatedAt, &invoice.CreatedBy,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find intercompany invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// CreateInvoices drafts the selling company's customer invoice to the buying
+// company and the buying company's mirrored vendor bill, and marks the sales
+// order invoiced. The trade must have both companies and the seller's partner.
+func (r *intercompanyRepository) CreateInvoices(ctx context.Context, orgID, userID uuid.UUID, trade types.IntercompanyTrade) (*types.IntercompanyInvoice, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoice := types.IntercompanyInvoice{
+		OrganizationID:  orgID,
+		SalesOrderID:    trade.OrderID,
+		SellerCompanyID: *trade.SellerCompanyID,
+		BuyerCompanyID:  *trade.BuyerCompanyID,
+		AmountTotal:     trade.AmountTotal,
+		CreatedBy:       &userID,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO intercompany_invoices (
+			organization_id, sales_order_id, seller_company_id, buyer_company_id, amount_total, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (sales_order_id) DO NOTHING
+		RETURNING id, created_at
+	`, orgID, trade.OrderID, invoice.SellerCompanyID, invoice.BuyerCompanyID, trade.AmountTotal, userID,
+	).Scan(&invoice.ID, &invoice.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlreadyInvoiced
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create intercompany invoice: %w", err)
+	}
+
+	customerInvoiceID, err := createIntercompanyMove(ctx, tx, orgID, userID, "out_invoice", "sale",
+		invoice.SellerCompanyID, trade.BuyerPartnerID, trade)
+	if err != nil {
+		return nil, err
+	}
+	vendorBillID, err := createIntercompanyMove(ctx, tx, orgID, userID, "in_invoice", "purchase",
+		invoice.BuyerCompanyID, *trade.SellerPartnerID, trade)
+	if err != nil {
+		return nil, err
+	}
+	invoice.CustomerInvoiceID = &customerInvoiceID
+	invoice.VendorBillID = &vendorBillID
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE intercompany_invoices SET customer_invoice_id = $2, vendor_bill_id = $3 WHERE id = $1
+	`, invoice.ID, customerInvoiceID, vendorBillID); err != nil {
+		return nil, fmt.Errorf("failed to link intercompany invoices: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sales_orders SET invoice_status = 'invoiced', updated_at = NOW(), updated_by = $2 WHERE id = $1
+	`, trade.OrderID, userID); err != nil {
+		return nil, fmt.Errorf("failed to mark sales order invoiced: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sales_order_lines
+		SET qty_invoiced = product_uom_qty, qty_to_invoice = 0, invoice_status = 'invoiced', updated_at = NOW()
+		WHERE order_id = $1 AND deleted_at IS NULL AND display_type IS NULL
+	`, trade.OrderID); err != nil {
+		return nil, fmt.Errorf("failed to mark sales order lines invoiced: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit intercompany invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// createIntercompanyMove drafts one side of an intercompany sale in the
+// company's journal of the given type: a customer invoice credits the sale
+// account, a vendor bill debits the purchase account
+func createIntercompanyMove(ctx context.Context, tx *sql.Tx, orgID, userID uuid.UUID, moveType, journalType string, companyID, partnerID uuid.UUID, trade types.IntercompanyTrade) (uuid.UUID, error) {
+	var journalID, accountID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, default_account_id
+		FROM account_journals
+		WHERE organization_id = $1 AND type = $2 AND active = true AND default_account_id IS NOT NULL
+			AND (company_id = $3 OR company_id IS NULL)
+		ORDER BY company_id NULLS LAST, created_at
+		LIMIT 1
+	`, orgID, journalType, companyID).Scan(&journalID, &accountID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("%w: company %s has no %s journal", ErrNoIntercompanyJournal, companyID, journalType)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find %s journal: %w", journalType, err)
+	}
+
+	sign := 1.0
+	if moveType == "in_invoice" {
+		sign = -1
+	}
+	var moveID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO invoices (
+			organization_id, company_id, move_type, invoice_date, state, partner_id, journal_id, currency_id,
+			amount_untaxed, amount_tax, amount_total, amount_residual, amount_untaxed_signed, amount_total_signed,
+			invoice_origin, user_id, created_by
+		) VALUES ($1, $2, $3, CURRENT_DATE, 'draft', $4, $5, $6, $7, $8, $9, $9, $10, $11, $12, $13, $13)
+		RETURNING id
+	`, orgID, companyID, moveType, partnerID, journalID, trade.CurrencyID, trade.AmountUntaxed,
+		trade.AmountTax, trade.AmountTotal, sign*trade.AmountUntaxed, sign*trade.AmountTotal,
+		trade.OrderName, userID,
+	).Scan(&moveID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create intercompany %s: %w", moveType, err)
+	}
+
+	for i, line := range trade.Lines {
+		debit, credit := 0.0, line.Subtotal
+		if moveType == "in_invoice" {
+			debit, credit = line.Subtotal, 0
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO invoice_lines (
+				organization_id, company_id, move_id, sequence, name, account_id, product_id, quantity,
+				price_unit, debit, credit, balance, price_subtotal, price_total, partner_id, currency_id,
+				display_type, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $10 - $11, $12, $13, $14, $15, 'product', $16)
+		`, orgID, companyID, moveID, (i+1)*10, line.Name, accountID, line.ProductID, line.Quantity,
+			line.UnitPrice, debit, credit, line.Subtotal, line.Total, partnerID, trade.CurrencyID, userID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create intercompany %s line: %w", moveType, err)
+		}
+	}
+	return moveID, nil
+}
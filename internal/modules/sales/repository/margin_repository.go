@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrMarginApprovalPending is returned when the quote already has a
+	// pending margin approval
+	ErrMarginApprovalPending = errors.New("quote already has a pending margin approval")
+
+	// ErrMarginApprovalNotPending is returned when reviewing a margin
+	// approval that was already approved or rejected
+	ErrMarginApprovalNotPending = errors.New("margin approval is not pending")
+)
+
+type MarginRepository interface {
+	GetSettings(ctx context.Context, orgID uuid.UUID) (*types.MarginSettings, error)
+	SaveSettings(ctx context.Context, orgID, userID uuid.UUID, settings types.MarginSettings) (*types.MarginSettings, error)
+	FindMarginOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.MarginOrder, error)
+	CreateApproval(ctx context.Context, approval types.MarginApproval) (*types.MarginApproval, error)
+	FindApproval(ctx context.Context, orgID, id uuid.UUID) (*types.MarginApproval, error)
+	ListApprovals(ctx context.Context, filter types.MarginApprovalFilter) ([]types.MarginApproval, error)
+	ReviewApproval(ctx context.Context, orgID, id, userID uuid.UUID, status types.MarginApprovalStatus, note string) (*types.MarginApproval, error)
+	Analytics(ctx context.Context, filter types.MarginAnalyticsFilter, group types.MarginAnalyticsGroup) ([]types.MarginAnalyticsRow, error)
+}
+
+type marginRepository struct {
+	db *sql.DB
+}
+
+func NewMarginRepository(db *sql.DB) MarginRepository {
+	return &marginRepository{db: db}
+}
+
+// GetSettings returns nil when the organization has no margin settings
+func (r *marginRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.MarginSettings, error) {
+	var settings types.MarginSettings
+	var minMargin, minLineMargin sql.NullFloat64
+	var updatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT min_margin_percent, min_line_margin_percent, updated_at
+		FROM sales_margin_settings
+		WHERE organization_id = $1
+	`, orgID).Scan(&minMargin, &minLineMargin, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get margin settings: %w", err)
+	}
+	settings.MinMarginPercent = nullFloat(minMargin)
+	settings.MinLineMarginPercent = nullFloat(minLineMargin)
+	if updatedAt.Valid {
+		settings.UpdatedAt = &updatedAt.Time
+	}
+	return &settings, nil
+}
+
+func (r *marginRepository) SaveSettings(ctx context.Context, orgID, userID uuid.UUID, settings types.MarginSettings) (*types.MarginSettings, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sales_margin_settings (organization_id, min_margin_percent, min_line_margin_percent, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			min_margin_percent = EXCLUDED.min_margin_percent,
+			min_line_margin_percent = EXCLUDED.min_line_margin_percent,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+	`, orgID, settings.MinMarginPercent, settings.MinLineMarginPercent, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save margin settings: %w", err)
+	}
+	return r.GetSettings(ctx, orgID)
+}
+
+// FindMarginOrder reads an order's lines with their costs: the cost frozen
+// on confirmation, or the product's current standard price. It returns nil
+// when the order does not exist.
+func (r *marginRepository) FindMarginOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.MarginOrder, error) {
+	order := types.MarginOrder{OrderID: orderID, Lines: []types.MarginLine{}}
+	var userID uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, state, user_id
+		FROM sales_orders
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+	`, orderID, orgID).Scan(&order.Reference, &order.State, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find sales order: %w", err)
+	}
+	if userID.Valid {
+		order.UserID = &userID.UUID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.id, l.product_id, l.name, COALESCE(l.product_uom_qty, 0), COALESCE(l.price_unit, 0),
+			COALESCE(l.product_uom_qty, 0) * COALESCE(l.price_unit, 0) * (1 - COALESCE(l.discount, 0) / 100),
+			CASE WHEN l.product_id IS NULL THEN 0 ELSE COALESCE(l.cost_price, p.standard_price, 0) END,
+			l.cost_price IS NOT NULL, l.promotion_id IS NOT NULL
+		FROM sales_order_lines l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.order_id = $1 AND l.organization_id = $2 AND l.deleted_at IS NULL AND l.display_type IS NULL
+		ORDER BY l.sequence, l.created_at
+	`, orderID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sales order lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line types.MarginLine
+		var productID uuid.NullUUID
+		if err := rows.Scan(&line.LineID, &productID, &line.Name, &line.Quantity, &line.UnitPrice,
+			&line.Subtotal, &line.UnitCost, &line.CostFrozen, &line.IsReward); err != nil {
+			return nil, fmt.Errorf("failed to scan sales order line: %w", err)
+		}
+		if productID.Valid {
+			line.ProductID = &productID.UUID
+		}
+		order.Lines = append(order.Lines, line)
+	}
+	return &order, rows.Err()
+}
+
+const marginApprovalColumns = `
+	a.id, a.organization_id, a.order_id, COALESCE(so.name, ''), a.status, a.amount_untaxed, a.cost_total,
+	a.margin_percent, a.lowest_line_margin_percent, a.min_margin_percent, a.min_line_margin_percent,
+	COALESCE(a.request_note, ''), a.requested_by, a.requested_at, a.reviewed_by, a.reviewed_at,
+	COALESCE(a.review_note, '')
+`
+
+const marginApprovalFrom = ` FROM sales_margin_approvals a LEFT JOIN sales_orders so ON so.id = a.order_id `
+
+func scanMarginApproval(row interface{ Scan(...interface{}) error }) (*types.MarginApproval, error) {
+	var a types.MarginApproval
+	var marginPercent, lowestLine, minMargin, minLineMargin sql.NullFloat64
+	var reviewedBy uuid.NullUUID
+	var reviewedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.OrganizationID, &a.OrderID, &a.Reference, &a.Status, &a.AmountUntaxed, &a.CostTotal,
+		&marginPercent, &lowestLine, &minMargin, &minLineMargin,
+		&a.RequestNote, &a.RequestedBy, &a.RequestedAt, &reviewedBy, &reviewedAt, &a.ReviewNote)
+	if err != nil {
+		return nil, err
+	}
+	a.MarginPercent = nullFloat(marginPercent)
+	a.LowestLineMarginPercent = nullFloat(lowestLine)
+	a.MinMarginPercent = nullFloat(minMargin)
+	a.MinLineMarginPercent = nullFloat(minLineMargin)
+	if reviewedBy.Valid {
+		a.ReviewedBy = &reviewedBy.UUID
+	}
+	if reviewedAt.Valid {
+		a.ReviewedAt = &reviewedAt.Time
+	}
+	return &a, nil
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func (r *marginRepository) CreateApproval(ctx context.Context, approval types.MarginApproval) (*types.MarginApproval, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sales_margin_approvals (
+			organization_id, order_id, status, amount_untaxed, cost_total, margin_percent,
+			lowest_line_margin_percent, min_margin_percent, min_line_margin_percent, request_note, requested_by
+		) VALUES ($1, $2, 'pending', $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		RETURNING id
+	`, approval.OrganizationID, approval.OrderID, approval.AmountUntaxed, approval.CostTotal, approval.MarginPercent,
+		approval.LowestLineMarginPercent, approval.MinMarginPercent, approval.MinLineMarginPercent,
+		approval.RequestNote, approval.RequestedBy).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrMarginApprovalPending
+		}
+		return nil, fmt.Errorf("failed to create margin approval: %w", err)
+	}
+	return r.FindApproval(ctx, approval.OrganizationID, id)
+}
+
+// FindApproval returns nil when the margin approval does not exist
+func (r *marginRepository) FindApproval(ctx context.Context, orgID, id uuid.UUID) (*types.MarginApproval, error) {
+	a, err := scanMarginApproval(r.db.QueryRowContext(ctx, `
+		SELECT `+marginApprovalColumns+marginApprovalFrom+`
+		WHERE a.organization_id = $1 AND a.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find margin approval: %w", err)
+	}
+	return a, nil
+}
+
+// ListApprovals lists margin approvals, most recently requested first
+func (r *marginRepository) ListApprovals(ctx context.Context, filter types.MarginApprovalFilter) ([]types.MarginApproval, error) {
+	where := []string{"a.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		where = append(where, fmt.Sprintf("a.status = $%d", len(args)))
+	}
+	if filter.OrderID != nil {
+		args = append(args, *filter.OrderID)
+		where = append(where, fmt.Sprintf("a.order_id = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+marginApprovalColumns+marginApprovalFrom+`
+		WHERE `+strings.Join(where, " AND ")+fmt.Sprintf(`
+		ORDER BY a.requested_at DESC, a.id
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list margin approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []types.MarginApproval{}
+	for rows.Next() {
+		a, err := scanMarginApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan margin approval: %w", err)
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals, rows.Err()
+}
+
+// ReviewApproval approves or rejects a pending margin approval
+func (r *marginRepository) ReviewApproval(ctx context.Context, orgID, id, userID uuid.UUID, status types.MarginApprovalStatus, note string) (*types.MarginApproval, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sales_margin_approvals SET
+			status = $3, reviewed_by = $4, reviewed_at = NOW(), review_note = NULLIF($5, '')
+		WHERE organization_id = $1 AND id = $2 AND status = 'pending'
+	`, orgID, id, status, userID, note)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review margin approval: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrMarginApprovalNotPending
+	}
+	return r.FindApproval(ctx, orgID, id)
+}
+
+// Analytics totals the lines of the confirmed orders dated in [From, To) by
+// rep or product, or overall when group is empty. Costs are the ones frozen
+// on confirmation. Reward lines count towards rep totals but not products.
+func (r *marginRepository) Analytics(ctx context.Context, filter types.MarginAnalyticsFilter, group types.MarginAnalyticsGroup) ([]types.MarginAnalyticsRow, error) {
+	key, name, where := "NULL::uuid", "''", ""
+	switch group {
+	case types.MarginAnalyticsByRep:
+		key = "user_id"
+		name = `COALESCE((
+			SELECT e.name FROM employees e
+			WHERE e.organization_id = $1 AND e.user_id = m.user_id AND e.deleted_at IS NULL
+			ORDER BY e.created_at LIMIT 1
+		), '')`
+	case types.MarginAnalyticsByProduct:
+		key = "product_id"
+		name = "COALESCE((SELECT p.name FROM products p WHERE p.id = m.product_id), '')"
+		where = "WHERE product_id IS NOT NULL"
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH m AS (
+			SELECT so.id AS order_id, so.user_id, l.product_id,
+				COALESCE(l.product_uom_qty, 0) AS quantity,
+				COALESCE(l.product_uom_qty, 0) * COALESCE(l.price_unit, 0) * (1 - COALESCE(l.discount, 0) / 100) AS revenue,
+				CASE WHEN l.product_id IS NULL THEN 0
+					ELSE COALESCE(l.product_uom_qty, 0) * COALESCE(l.cost_price, p.standard_price, 0) END AS cost,
+				EXISTS (
+					SELECT 1 FROM sales_margin_approvals a WHERE a.order_id = so.id AND a.status = 'approved'
+				) AS approved
+			FROM sales_orders so
+			JOIN sales_order_lines l ON l.order_id = so.id AND l.deleted_at IS NULL AND l.display_type IS NULL
+			LEFT JOIN products p ON p.id = l.product_id
+			WHERE so.organization_id = $1 AND so.state IN ('sale', 'done') AND so.deleted_at IS NULL
+				AND ($2::timestamptz IS NULL OR so.date_order >= $2)
+				AND ($3::timestamptz IS NULL OR so.date_order < $3)
+		)
+		SELECT `+key+`, `+name+`, COUNT(DISTINCT order_id), COALESCE(SUM(quantity), 0),
+			COALESCE(ROUND(SUM(revenue), 2), 0), COALESCE(ROUND(SUM(cost), 2), 0),
+			COUNT(DISTINCT order_id) FILTER (WHERE approved)
+		FROM m
+		`+where+`
+		GROUP BY 1
+		ORDER BY SUM(revenue) - SUM(cost) DESC NULLS LAST
+	`, filter.OrganizationID, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute margin analytics: %w", err)
+	}
+	defer rows.Close()
+
+	report := []types.MarginAnalyticsRow{}
+	for rows.Next() {
+		var row types.MarginAnalyticsRow
+		var keyID uuid.NullUUID
+		if err := rows.Scan(&keyID, &row.Name, &row.Orders, &row.Quantity, &row.Revenue, &row.Cost,
+			&row.ApprovedOrders); err != nil {
+			return nil, fmt.Errorf("failed to scan margin analytics: %w", err)
+		}
+		if keyID.Valid {
+			row.Key = &keyID.UUID
+		}
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
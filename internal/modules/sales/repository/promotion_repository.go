@@ -0,0 +1,447 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrCouponInUse is returned when another promotion already has the coupon code
+var ErrCouponInUse = errors.New("coupon code is already used by another promotion")
+
+type PromotionRepository interface {
+	ListPromotions(ctx context.Context, orgID uuid.UUID) ([]types.Promotion, error)
+	FindPromotion(ctx context.Context, orgID, id uuid.UUID) (*types.Promotion, error)
+	CreatePromotion(ctx context.Context, orgID, userID uuid.UUID, request types.PromotionRequest) (*types.Promotion, error)
+	UpdatePromotion(ctx context.Context, orgID, userID, id uuid.UUID, request types.PromotionRequest) (*types.Promotion, error)
+	FindPricingOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.PricingOrder, error)
+	ActivePromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.Promotion, error)
+	ApplyPricing(ctx context.Context, orgID, userID, orderID uuid.UUID, applied []types.AppliedPromotion) (*types.OrderPricing, error)
+	ListOrderPromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.AppliedPromotion, error)
+	Performance(ctx context.Context, orgID uuid.UUID, promotionID *uuid.UUID, from, to *time.Time) ([]types.PromotionPerformance, error)
+}
+
+type promotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) PromotionRepository {
+	return &promotionRepository{db: db}
+}
+
+// promotionColumns selects a promotion and how many open or confirmed orders
+// other than $2 use it
+const promotionColumns = `
+	p.id, p.organization_id, p.name, COALESCE(p.description, ''), p.promotion_type, p.value, p.coupon_code,
+	p.product_ids, p.min_order_amount, p.buy_product_id, COALESCE(p.buy_quantity, 0), p.reward_product_id,
+	COALESCE(p.reward_quantity, 0), p.segment_id, p.starts_at, p.ends_at, p.stackable, p.priority, p.usage_limit,
+	(
+		SELECT COUNT(*) FROM sales_order_promotions a
+		JOIN sales_orders so ON so.id = a.order_id
+		WHERE a.promotion_id = p.id AND a.order_id <> $2 AND so.state <> 'cancel' AND so.deleted_at IS NULL
+	),
+	p.active, p.created_at, p.updated_at
+`
+
+func scanPromotion(row interface{ Scan(...interface{}) error }) (*types.Promotion, error) {
+	var p types.Promotion
+	var couponCode sql.NullString
+	var productIDs pq.StringArray
+	var buyProductID, rewardProductID, segmentID uuid.NullUUID
+	var startsAt, endsAt sql.NullTime
+	var usageLimit sql.NullInt64
+	err := row.Scan(&p.ID, &p.OrganizationID, &p.Name, &p.Description, &p.Type, &p.Value, &couponCode,
+		&productIDs, &p.MinOrderAmount, &buyProductID, &p.BuyQuantity, &rewardProductID,
+		&p.RewardQuantity, &segmentID, &startsAt, &endsAt, &p.Stackable, &p.Priority, &usageLimit,
+		&p.TimesUsed, &p.Active, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if couponCode.Valid {
+		p.CouponCode = &couponCode.String
+	}
+	p.ProductIDs = make([]uuid.UUID, 0, len(productIDs))
+	for _, id := range productIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid promotion product: %w", err)
+		}
+		p.ProductIDs = append(p.ProductIDs, parsed)
+	}
+	if buyProductID.Valid {
+		p.BuyProductID = &buyProductID.UUID
+	}
+	if rewardProductID.Valid {
+		p.RewardProductID = &rewardProductID.UUID
+	}
+	if segmentID.Valid {
+		p.SegmentID = &segmentID.UUID
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	if usageLimit.Valid {
+		limit := int(usageLimit.Int64)
+		p.UsageLimit = &limit
+	}
+	return &p, nil
+}
+
+func (r *promotionRepository) listPromotions(ctx context.Context, orgID, orderID uuid.UUID, where string) ([]types.Promotion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+promotionColumns+`
+		FROM sales_promotions p
+		WHERE p.organization_id = $1 `+where+`
+		ORDER BY p.priority, p.name
+	`, orgID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []types.Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, *p)
+	}
+	return promotions, rows.Err()
+}
+
+func (r *promotionRepository) ListPromotions(ctx context.Context, orgID uuid.UUID) ([]types.Promotion, error) {
+	return r.listPromotions(ctx, orgID, uuid.Nil, "")
+}
+
+// ActivePromotions returns the active promotions, counting their uses on
+// orders other than the one being priced
+func (r *promotionRepository) ActivePromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.Promotion, error) {
+	return r.listPromotions(ctx, orgID, orderID, "AND p.active")
+}
+
+// FindPromotion returns nil when the promotion does not exist
+func (r *promotionRepository) FindPromotion(ctx context.Context, orgID, id uuid.UUID) (*types.Promotion, error) {
+	p, err := scanPromotion(r.db.QueryRowContext(ctx, `
+		SELECT `+promotionColumns+`
+		FROM sales_promotions p
+		WHERE p.organization_id = $1 AND p.id = $3
+	`, orgID, uuid.Nil, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find promotion: %w", err)
+	}
+	return p, nil
+}
+
+func promotionArgs(request types.PromotionRequest) []interface{} {
+	productIDs := make([]string, 0, len(request.ProductIDs))
+	for _, id := range request.ProductIDs {
+		productIDs = append(productIDs, id.String())
+	}
+	var buyQuantity, rewardQuantity *float64
+	if request.Type == types.PromotionTypeBuyXGetY {
+		buyQuantity, rewardQuantity = &request.BuyQuantity, &request.RewardQuantity
+	}
+	return []interface{}{
+		request.Name, request.Description, request.Type, request.Value, request.CouponCode,
+		pq.Array(productIDs), request.MinOrderAmount, request.BuyProductID, buyQuantity, request.RewardProductID,
+		rewardQuantity, request.SegmentID, request.StartsAt, request.EndsAt, request.Stackable == nil || *request.Stackable,
+		request.Priority, request.UsageLimit, request.Active == nil || *request.Active,
+	}
+}
+
+func promotionWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrCouponInUse
+	}
+	return fmt.Errorf("failed to save promotion: %w", err)
+}
+
+func (r *promotionRepository) CreatePromotion(ctx context.Context, orgID, userID uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	var id uuid.UUID
+	args := append([]interface{}{orgID, userID}, promotionArgs(request)...)
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sales_promotions (
+			organization_id, created_by, updated_by, name, description, promotion_type, value, coupon_code,
+			product_ids, min_order_amount, buy_product_id, buy_quantity, reward_product_id, reward_quantity,
+			segment_id, starts_at, ends_at, stackable, priority, usage_limit, active
+		) VALUES ($1, $2, $2, $3, NULLIF($4, ''), $5, $6, $7, $8::uuid[], $9, $10, $11, $12, $13, $14, $15, $16,
+			$17, $18, $19, $20)
+		RETURNING id
+	`, args...).Scan(&id)
+	if err != nil {
+		return nil, promotionWriteError(err)
+	}
+	return r.FindPromotion(ctx, orgID, id)
+}
+
+// UpdatePromotion replaces a promotion. It returns nil when the promotion does not exist.
+func (r *promotionRepository) UpdatePromotion(ctx context.Context, orgID, userID, id uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	args := append([]interface{}{orgID, userID, id}, promotionArgs(request)...)
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sales_promotions SET
+			updated_by = $2, name = $4, description = NULLIF($5, ''), promotion_type = $6, value = $7,
+			coupon_code = $8, product_ids = $9::uuid[], min_order_amount = $10, buy_product_id = $11,
+			buy_quantity = $12, reward_product_id = $13, reward_quantity = $14, segment_id = $15,
+			starts_at = $16, ends_at = $17, stackable = $18, priority = $19, usage_limit = $20, active = $21,
+			updated_at = NOW()
+		WHERE organization_id = $1 AND id = $3
+	`, args...)
+	if err != nil {
+		return nil, promotionWriteError(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+	return r.FindPromotion(ctx, orgID, id)
+}
+
+// FindPricingOrder reads an order's product lines, the customer's segments
+// and the coupon codes already applied. It returns nil when the order does
+// not exist.
+func (r *promotionRepository) FindPricingOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.PricingOrder, error) {
+	order := types.PricingOrder{OrderID: orderID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT state, partner_id, COALESCE(date_order, created_at)
+		FROM sales_orders
+		WHERE id = $1 AND organization_id = $2 AND deleted_at IS NULL
+	`, orderID, orgID).Scan(&order.State, &order.CustomerID, &order.OrderDate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find sales order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, product_id, COALESCE(product_uom_qty, 0), COALESCE(price_unit, 0),
+			COALESCE(product_uom_qty, 0) * COALESCE(price_unit, 0) * (1 - COALESCE(discount, 0) / 100)
+		FROM sales_order_lines
+		WHERE order_id = $1 AND organization_id = $2 AND deleted_at IS NULL
+			AND display_type IS NULL AND promotion_id IS NULL
+		ORDER BY sequence, created_at
+	`, orderID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sales order lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line types.PricingLine
+		var productID uuid.NullUUID
+		if err := rows.Scan(&line.LineID, &productID, &line.Quantity, &line.UnitPrice, &line.Subtotal); err != nil {
+			return nil, fmt.Errorf("failed to scan sales order line: %w", err)
+		}
+		if productID.Valid {
+			line.ProductID = &productID.UUID
+		}
+		order.Lines = append(order.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var segmentIDs pq.StringArray
+	var couponCodes pq.StringArray
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			ARRAY(
+				SELECT DISTINCT segment_id::text FROM contact_segment_members
+				WHERE organization_id = $1
+					AND contact_id IN ($2, (SELECT parent_id FROM contacts WHERE id = $2))
+			),
+			ARRAY(
+				SELECT coupon_code FROM sales_order_promotions
+				WHERE order_id = $3 AND coupon_code IS NOT NULL
+			)
+	`, orgID, order.CustomerID, orderID).Scan(&segmentIDs, &couponCodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find customer segments: %w", err)
+	}
+	for _, id := range segmentIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment: %w", err)
+		}
+		order.SegmentIDs = append(order.SegmentIDs, parsed)
+	}
+	order.CouponCodes = couponCodes
+	return &order, nil
+}
+
+// ApplyPricing replaces the order's reward lines and applied promotions and
+// recomputes its amounts
+func (r *promotionRepository) ApplyPricing(ctx context.Context, orgID, userID, orderID uuid.UUID, applied []types.AppliedPromotion) (*types.OrderPricing, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM sales_order_lines WHERE order_id = $1 AND organization_id = $2 AND promotion_id IS NOT NULL
+	`, orderID, orgID); err != nil {
+		return nil, fmt.Errorf("failed to remove reward lines: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM sales_order_promotions WHERE order_id = $1 AND organization_id = $2
+	`, orderID, orgID); err != nil {
+		return nil, fmt.Errorf("failed to remove applied promotions: %w", err)
+	}
+
+	pricing := &types.OrderPricing{OrderID: orderID, Promotions: []types.AppliedPromotion{}}
+	for i, a := range applied {
+		// Reward lines come after the product lines
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO sales_order_lines (
+				organization_id, order_id, sequence, name, product_uom_qty, price_unit, price_subtotal,
+				price_total, promotion_id, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, 1, $5, $5, $5, $6, $7, $7)
+		`, orgID, orderID, 10000+i*10, "Promotion: "+a.Name, -a.Discount, a.PromotionID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add reward line: %w", err)
+		}
+
+		var appliedAt time.Time
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO sales_order_promotions (organization_id, order_id, promotion_id, coupon_code, discount_amount, applied_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING applied_at
+		`, orgID, orderID, a.PromotionID, a.CouponCode, a.Discount, userID).Scan(&appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record applied promotion: %w", err)
+		}
+		a.AppliedAt = &appliedAt
+		pricing.Promotions = append(pricing.Promotions, a)
+		pricing.Discount += a.Discount
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE sales_orders so SET
+			amount_untaxed = t.untaxed,
+			amount_tax = t.tax,
+			amount_total = t.total,
+			amount_discount = $3,
+			updated_by = $4,
+			updated_at = NOW()
+		FROM (
+			SELECT COALESCE(SUM(price_subtotal), 0) AS untaxed, COALESCE(SUM(price_tax), 0) AS tax,
+				COALESCE(SUM(price_total), 0) AS total
+			FROM sales_order_lines
+			WHERE order_id = $1 AND deleted_at IS NULL AND display_type IS NULL
+		) t
+		WHERE so.id = $1 AND so.organization_id = $2
+		RETURNING so.amount_untaxed, so.amount_total
+	`, orderID, orgID, pricing.Discount, userID).Scan(&pricing.AmountUntaxed, &pricing.AmountTotal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order amounts: %w", err)
+	}
+	pricing.Subtotal = pricing.AmountUntaxed + pricing.Discount
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit pricing: %w", err)
+	}
+	return pricing, nil
+}
+
+func (r *promotionRepository) ListOrderPromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.AppliedPromotion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.promotion_id, p.name, a.coupon_code, a.discount_amount, a.applied_at
+		FROM sales_order_promotions a
+		JOIN sales_promotions p ON p.id = a.promotion_id
+		WHERE a.order_id = $1 AND a.organization_id = $2
+		ORDER BY a.applied_at, p.priority
+	`, orderID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied promotions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := []types.AppliedPromotion{}
+	for rows.Next() {
+		var a types.AppliedPromotion
+		var couponCode sql.NullString
+		var appliedAt time.Time
+		if err := rows.Scan(&a.PromotionID, &a.Name, &couponCode, &a.Discount, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied promotion: %w", err)
+		}
+		if couponCode.Valid {
+			a.CouponCode = &couponCode.String
+		}
+		a.AppliedAt = &appliedAt
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// Performance aggregates the orders dated in [from, to) each promotion is
+// applied to. Discounts and revenue only count confirmed orders; usage
+// counts every order that is not cancelled, whatever its date.
+func (r *promotionRepository) Performance(ctx context.Context, orgID uuid.UUID, promotionID *uuid.UUID, from, to *time.Time) ([]types.PromotionPerformance, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.id, p.name, p.coupon_code, p.usage_limit,
+			(
+				SELECT COUNT(*) FROM sales_order_promotions ua
+				JOIN sales_orders uo ON uo.id = ua.order_id
+				WHERE ua.promotion_id = p.id AND uo.state <> 'cancel' AND uo.deleted_at IS NULL
+			),
+			COUNT(so.id) FILTER (WHERE so.state <> 'cancel'),
+			COUNT(so.id) FILTER (WHERE so.state IN ('sale', 'done')),
+			COUNT(DISTINCT so.partner_id) FILTER (WHERE so.state <> 'cancel'),
+			COALESCE(SUM(a.discount_amount) FILTER (WHERE so.state IN ('sale', 'done')), 0),
+			COALESCE(SUM(so.amount_untaxed) FILTER (WHERE so.state IN ('sale', 'done')), 0)
+		FROM sales_promotions p
+		LEFT JOIN (
+			sales_order_promotions a
+			JOIN sales_orders so ON so.id = a.order_id AND so.deleted_at IS NULL
+				AND ($3::timestamptz IS NULL OR so.date_order >= $3)
+				AND ($4::timestamptz IS NULL OR so.date_order < $4)
+		) ON a.promotion_id = p.id
+		WHERE p.organization_id = $1 AND ($2::uuid IS NULL OR p.id = $2)
+		GROUP BY p.id
+		ORDER BY p.name
+	`, orgID, promotionID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute promotion performance: %w", err)
+	}
+	defer rows.Close()
+
+	report := []types.PromotionPerformance{}
+	for rows.Next() {
+		var perf types.PromotionPerformance
+		var couponCode sql.NullString
+		var usageLimit sql.NullInt64
+		var used int
+		if err := rows.Scan(&perf.PromotionID, &perf.Name, &couponCode, &usageLimit, &used, &perf.Orders,
+			&perf.ConfirmedOrders, &perf.Customers, &perf.DiscountTotal, &perf.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion performance: %w", err)
+		}
+		if couponCode.Valid {
+			perf.CouponCode = &couponCode.String
+		}
+		if usageLimit.Valid {
+			limit := int(usageLimit.Int64)
+			remaining := limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			perf.UsageLimit, perf.RemainingUses = &limit, &remaining
+		}
+		report = append(report, perf)
+	}
+	return report, rows.Err()
+}
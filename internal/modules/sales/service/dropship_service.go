@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidDropship is returned for dropship requests that fail validation
+	ErrInvalidDropship = errors.New("invalid dropship request")
+
+	// ErrLineAlreadyOrdered is returned when changing or reordering a dropship
+	// line that has already been ordered from its vendor
+	ErrLineAlreadyOrdered = repository.ErrLineAlreadyOrdered
+)
+
+type DropshipService struct {
+	repo repository.DropshipRepository
+}
+
+func NewDropshipService(repo repository.DropshipRepository) *DropshipService {
+	return &DropshipService{
+		repo: repo,
+	}
+}
+
+// SetLineRoute makes a sales order line a stock or a dropship line. It
+// returns nil when the line or the vendor does not exist.
+func (s *DropshipService) SetLineRoute(ctx context.Context, orgID, orderID, lineID uuid.UUID, request types.SalesLineRouteRequest) (*types.SalesLineRouting, error) {
+	if !request.Route.IsValid() {
+		return nil, fmt.Errorf("%w: route must be stock or dropship", ErrInvalidDropship)
+	}
+
+	if request.Route == types.SalesLineRouteStock {
+		request.VendorID = nil
+		request.UnitCost = nil
+	} else {
+		if request.VendorID == nil || *request.VendorID == uuid.Nil {
+			return nil, fmt.Errorf("%w: vendor_id is required for dropship lines", ErrInvalidDropship)
+		}
+		if request.UnitCost != nil && *request.UnitCost < 0 {
+			return nil, fmt.Errorf("%w: unit_cost cannot be negative", ErrInvalidDropship)
+		}
+	}
+
+	return s.repo.SetLineRoute(ctx, orgID, orderID, lineID, request)
+}
+
+// CreatePurchaseOrders orders the confirmed sales order's pending dropship
+// lines with one purchase order per vendor, delivered to the customer. When
+// ordering from a vendor fails, the purchase orders already created are kept
+// and a retry only orders the remaining lines. It returns nil when the sales
+// order does not exist.
+func (s *DropshipService) CreatePurchaseOrders(ctx context.Context, orgID, userID, orderID uuid.UUID) ([]types.DropshipOrder, error) {
+	source, err := s.repo.FindSource(ctx, orgID, orderID)
+	if err != nil || source == nil {
+		return nil, err
+	}
+	if source.State != "sale" {
+		return nil, fmt.Errorf("%w: the sales order must be confirmed before ordering its dropship lines", ErrInvalidDropship)
+	}
+	if len(source.Lines) == 0 {
+		return nil, fmt.Errorf("%w: the sales order has no dropship lines left to order", ErrInvalidDropship)
+	}
+
+	var vendors []uuid.UUID
+	linesByVendor := make(map[uuid.UUID][]types.DropshipLine)
+	for _, line := range source.Lines {
+		if line.VendorID == nil {
+			return nil, fmt.Errorf("%w: dropship line %s has no vendor", ErrInvalidDropship, line.SalesLineID)
+		}
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: dropship line %s has no quantity", ErrInvalidDropship, line.SalesLineID)
+		}
+		if _, ok := linesByVendor[*line.VendorID]; !ok {
+			vendors = append(vendors, *line.VendorID)
+		}
+		linesByVendor[*line.VendorID] = append(linesByVendor[*line.VendorID], line)
+	}
+
+	dropships := make([]types.DropshipOrder, 0, len(vendors))
+	for _, vendorID := range vendors {
+		dropship, err := s.repo.CreateDropship(ctx, orgID, userID, *source, vendorID, linesByVendor[vendorID])
+		if err != nil {
+			return nil, fmt.Errorf("failed to order dropship lines from vendor %s: %w", vendorID, err)
+		}
+		dropships = append(dropships, *dropship)
+	}
+	return dropships, nil
+}
+
+// ListDropships lists the dropship orders of a sales order
+func (s *DropshipService) ListDropships(ctx context.Context, orgID, orderID uuid.UUID) ([]types.DropshipOrder, error) {
+	dropships, err := s.repo.ListDropships(ctx, orgID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if dropships == nil {
+		dropships = []types.DropshipOrder{}
+	}
+	return dropships, nil
+}
+
+// GetDropship returns a dropship order, or nil when it does not exist
+func (s *DropshipService) GetDropship(ctx context.Context, orgID, id uuid.UUID) (*types.DropshipOrder, error) {
+	return s.repo.FindDropship(ctx, orgID, id)
+}
+
+// RecordTracking links the tracking data the vendor reported for a dropship
+// order to its delivery shipment. It returns nil when the dropship order
+// does not exist.
+func (s *DropshipService) RecordTracking(ctx context.Context, orgID, userID, id uuid.UUID, request types.DropshipTrackingRequest) (*types.DropshipOrder, error) {
+	request.CarrierName = strings.TrimSpace(request.CarrierName)
+	request.CarrierCode = strings.TrimSpace(request.CarrierCode)
+	request.TrackingNumber = strings.TrimSpace(request.TrackingNumber)
+	request.TrackingURL = strings.TrimSpace(request.TrackingURL)
+	request.Status = strings.TrimSpace(request.Status)
+	request.Message = strings.TrimSpace(request.Message)
+
+	if request.TrackingNumber == "" && request.Status == "" {
+		return nil, fmt.Errorf("%w: tracking_number or status is required", ErrInvalidDropship)
+	}
+	if request.Status != "" && !types.DropshipShipmentStatuses[request.Status] {
+		return nil, fmt.Errorf("%w: unknown shipment status %q", ErrInvalidDropship, request.Status)
+	}
+	if request.TrackingURL != "" {
+		u, err := url.Parse(request.TrackingURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: tracking_url must be an http or https URL", ErrInvalidDropship)
+		}
+	}
+
+	dropship, err := s.repo.FindDropship(ctx, orgID, id)
+	if err != nil || dropship == nil {
+		return nil, err
+	}
+	if dropship.PurchaseState == "cancel" {
+		return nil, fmt.Errorf("%w: the dropship purchase order is cancelled", ErrInvalidDropship)
+	}
+
+	if err := s.repo.RecordTracking(ctx, orgID, userID, *dropship, request); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDropship(ctx, orgID, id)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidIntercompany is returned for intercompany requests that fail validation
+	ErrInvalidIntercompany = errors.New("invalid intercompany request")
+
+	// ErrAlreadyInvoiced is returned when an intercompany sale already has its invoices
+	ErrAlreadyInvoiced = repository.ErrAlreadyInvoiced
+
+	// ErrPartnerInUse is returned when a contact already represents another company
+	ErrPartnerInUse = repository.ErrPartnerInUse
+
+	// ErrNoIntercompanyJournal is returned when a company has no journal to
+	// book its side of an intercompany sale in
+	ErrNoIntercompanyJournal = repository.ErrNoIntercompanyJournal
+)
+
+type IntercompanyService struct {
+	repo repository.IntercompanyRepository
+}
+
+func NewIntercompanyService(repo repository.IntercompanyRepository) *IntercompanyService {
+	return &IntercompanyService{
+		repo: repo,
+	}
+}
+
+// ListPartners lists the contacts the organization's companies trade under
+func (s *IntercompanyService) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.IntercompanyPartner, error) {
+	partners, err := s.repo.ListPartners(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if partners == nil {
+		partners = []types.IntercompanyPartner{}
+	}
+	return partners, nil
+}
+
+// SavePartner links a company to the contact it trades under. It returns nil
+// when the company or the contact does not exist.
+func (s *IntercompanyService) SavePartner(ctx context.Context, orgID uuid.UUID, request types.IntercompanyPartnerRequest) (*types.IntercompanyPartner, error) {
+	if request.CompanyID == uuid.Nil || request.PartnerID == uuid.Nil {
+		return nil, fmt.Errorf("%w: company_id and partner_id are required", ErrInvalidIntercompany)
+	}
+	return s.repo.SavePartner(ctx, orgID, request)
+}
+
+// GetInvoice returns the intercompany invoices of a sales order, or nil when
+// it has none
+func (s *IntercompanyService) GetInvoice(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyInvoice, error) {
+	return s.repo.FindInvoice(ctx, orgID, orderID)
+}
+
+// InvoiceOrder invoices a confirmed sale to another company of the
+// organization: the selling company gets a customer invoice to the buyer and
+// the buying company a vendor bill from the seller, both as drafts. It
+// returns nil when the sales order does not exist.
+func (s *IntercompanyService) InvoiceOrder(ctx context.Context, orgID, userID, orderID uuid.UUID) (*types.IntercompanyInvoice, error) {
+	trade, err := s.repo.FindTrade(ctx, orgID, orderID)
+	if err != nil || trade == nil {
+		return nil, err
+	}
+
+	if trade.State != "sale" && trade.State != "done" {
+		return nil, fmt.Errorf("%w: only confirmed sales orders can be invoiced", ErrInvalidIntercompany)
+	}
+	if trade.BuyerCompanyID == nil {
+		return nil, fmt.Errorf("%w: the customer is not a company of the organization", ErrInvalidIntercompany)
+	}
+	if trade.SellerCompanyID == nil {
+		return nil, fmt.Errorf("%w: the sales order has no company", ErrInvalidIntercompany)
+	}
+	if *trade.SellerCompanyID == *trade.BuyerCompanyID {
+		return nil, fmt.Errorf("%w: a company cannot sell to itself", ErrInvalidIntercompany)
+	}
+	if trade.SellerPartnerID == nil {
+		return nil, fmt.Errorf("%w: the selling company %s has no intercompany partner", ErrInvalidIntercompany, *trade.SellerCompanyID)
+	}
+	if len(trade.Lines) == 0 {
+		return nil, fmt.Errorf("%w: the sales order has no lines", ErrInvalidIntercompany)
+	}
+
+	return s.repo.CreateInvoices(ctx, orgID, userID, *trade)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidMargin is returned for margin settings, approvals and
+	// analytics requests that fail validation
+	ErrInvalidMargin = errors.New("invalid margin request")
+
+	// ErrMarginApprovalPending is returned when the quote already has a
+	// pending margin approval
+	ErrMarginApprovalPending = repository.ErrMarginApprovalPending
+
+	// ErrMarginApprovalNotPending is returned when reviewing a margin
+	// approval that was already approved or rejected
+	ErrMarginApprovalNotPending = repository.ErrMarginApprovalNotPending
+)
+
+type MarginService struct {
+	repo     repository.MarginRepository
+	eventBus *events.Bus
+}
+
+func NewMarginService(repo repository.MarginRepository) *MarginService {
+	return &MarginService{
+		repo: repo,
+	}
+}
+
+// NewMarginServiceWithEventBus creates a margin service that publishes
+// margin approval events
+func NewMarginServiceWithEventBus(repo repository.MarginRepository, eventBus *events.Bus) *MarginService {
+	service := NewMarginService(repo)
+	service.eventBus = eventBus
+	return service
+}
+
+// GetSettings returns the organization's minimum margins. Organizations
+// without settings have no thresholds.
+func (s *MarginService) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.MarginSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &types.MarginSettings{}
+	}
+	return settings, nil
+}
+
+// UpdateSettings replaces the organization's minimum margins. Quotes that
+// were approved keep their approval.
+func (s *MarginService) UpdateSettings(ctx context.Context, orgID, userID uuid.UUID, settings types.MarginSettings) (*types.MarginSettings, error) {
+	for name, threshold := range map[string]*float64{
+		"min_margin_percent":      settings.MinMarginPercent,
+		"min_line_margin_percent": settings.MinLineMarginPercent,
+	} {
+		if threshold != nil && (*threshold < -100 || *threshold > 100) {
+			return nil, fmt.Errorf("%w: %s must be between -100 and 100", ErrInvalidMargin, name)
+		}
+	}
+	return s.repo.SaveSettings(ctx, orgID, userID, settings)
+}
+
+// GetQuoteMargin returns the cost and margin of each line of an order and of
+// the order as a whole, and whether it needs a margin approval to be
+// confirmed. It returns nil when the order does not exist.
+func (s *MarginService) GetQuoteMargin(ctx context.Context, orgID, orderID uuid.UUID) (*types.QuoteMargin, error) {
+	order, err := s.repo.FindMarginOrder(ctx, orgID, orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	settings, err := s.GetSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := s.repo.ListApprovals(ctx, types.MarginApprovalFilter{
+		OrganizationID: orgID,
+		OrderID:        &orderID,
+		Limit:          100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	margin := computeMargin(*order, *settings)
+	if len(approvals) > 0 {
+		margin.Approval = &approvals[0]
+	}
+	if margin.RequiresApproval {
+		for _, approval := range approvals {
+			if approvalCovers(approval, margin) {
+				margin.Approved = true
+				break
+			}
+		}
+	}
+	return &margin, nil
+}
+
+// RequestApproval routes a quote below the minimum margins for approval.
+// It returns nil when the order does not exist.
+func (s *MarginService) RequestApproval(ctx context.Context, orgID, userID, orderID uuid.UUID, request types.MarginApprovalRequest) (*types.MarginApproval, error) {
+	margin, err := s.GetQuoteMargin(ctx, orgID, orderID)
+	if err != nil || margin == nil {
+		return nil, err
+	}
+	if margin.State != "draft" && margin.State != "sent" {
+		return nil, fmt.Errorf("%w: only quotations can be routed for margin approval", ErrInvalidMargin)
+	}
+	if !margin.RequiresApproval {
+		return nil, fmt.Errorf("%w: the quote meets the minimum margins", ErrInvalidMargin)
+	}
+	if margin.Approved {
+		return nil, fmt.Errorf("%w: the quote is already approved at its current margin", ErrInvalidMargin)
+	}
+
+	approval, err := s.repo.CreateApproval(ctx, types.MarginApproval{
+		OrganizationID:          orgID,
+		OrderID:                 orderID,
+		AmountUntaxed:           margin.AmountUntaxed,
+		CostTotal:               margin.CostTotal,
+		MarginPercent:           margin.MarginPercent,
+		LowestLineMarginPercent: margin.LowestLineMarginPercent,
+		MinMarginPercent:        margin.Settings.MinMarginPercent,
+		MinLineMarginPercent:    margin.Settings.MinLineMarginPercent,
+		RequestNote:             strings.TrimSpace(request.Note),
+		RequestedBy:             userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "sales.margin_approval.requested", approval)
+	return approval, nil
+}
+
+// GetApproval returns a margin approval, or nil when it does not exist
+func (s *MarginService) GetApproval(ctx context.Context, orgID, id uuid.UUID) (*types.MarginApproval, error) {
+	return s.repo.FindApproval(ctx, orgID, id)
+}
+
+// ListApprovals lists margin approvals, most recently requested first
+func (s *MarginService) ListApprovals(ctx context.Context, filter types.MarginApprovalFilter) ([]types.MarginApproval, error) {
+	if filter.Status != nil && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: status must be pending, approved or rejected", ErrInvalidMargin)
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListApprovals(ctx, filter)
+}
+
+// ApproveApproval lets the quote be confirmed at the margin it was routed
+// with. Users cannot approve their own requests. It returns nil when the
+// approval does not exist.
+func (s *MarginService) ApproveApproval(ctx context.Context, orgID, id, userID uuid.UUID, review types.MarginApprovalReview) (*types.MarginApproval, error) {
+	return s.review(ctx, orgID, id, userID, types.MarginApprovalStatusApproved, strings.TrimSpace(review.Note))
+}
+
+// RejectApproval rejects a pending margin approval; the quote cannot be
+// confirmed until its margin is raised or another approval is granted. It
+// returns nil when the approval does not exist.
+func (s *MarginService) RejectApproval(ctx context.Context, orgID, id, userID uuid.UUID, review types.MarginApprovalReview) (*types.MarginApproval, error) {
+	note := strings.TrimSpace(review.Note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: a note is required to reject a margin approval", ErrInvalidMargin)
+	}
+	return s.review(ctx, orgID, id, userID, types.MarginApprovalStatusRejected, note)
+}
+
+func (s *MarginService) review(ctx context.Context, orgID, id, userID uuid.UUID, status types.MarginApprovalStatus, note string) (*types.MarginApproval, error) {
+	approval, err := s.repo.FindApproval(ctx, orgID, id)
+	if err != nil || approval == nil {
+		return nil, err
+	}
+	if approval.Status != types.MarginApprovalStatusPending {
+		return nil, ErrMarginApprovalNotPending
+	}
+	if approval.RequestedBy == userID {
+		return nil, fmt.Errorf("%w: margin approvals must be reviewed by another user", ErrInvalidMargin)
+	}
+
+	approval, err = s.repo.ReviewApproval(ctx, orgID, id, userID, status, note)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, "sales.margin_approval."+string(status), approval)
+	return approval, nil
+}
+
+// Analytics breaks the margin of the confirmed orders dated in [From, To)
+// down by sales rep or by product
+func (s *MarginService) Analytics(ctx context.Context, filter types.MarginAnalyticsFilter) (*types.MarginAnalytics, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = types.MarginAnalyticsByRep
+	}
+	if !filter.GroupBy.IsValid() {
+		return nil, fmt.Errorf("%w: group_by must be rep or product", ErrInvalidMargin)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidMargin)
+	}
+
+	report := &types.MarginAnalytics{GroupBy: filter.GroupBy, From: filter.From, To: filter.To}
+	totals, err := s.repo.Analytics(ctx, filter, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Total = totals[0]
+	}
+	if report.Rows, err = s.repo.Analytics(ctx, filter, filter.GroupBy); err != nil {
+		return nil, err
+	}
+	if report.Rows == nil {
+		report.Rows = []types.MarginAnalyticsRow{}
+	}
+
+	fillMargin(&report.Total)
+	for i := range report.Rows {
+		fillMargin(&report.Rows[i])
+	}
+	return report, nil
+}
+
+func fillMargin(row *types.MarginAnalyticsRow) {
+	row.Margin = roundAmount(row.Revenue - row.Cost)
+	row.MarginPercent = marginPercent(row.Revenue, row.Cost)
+}
+
+// computeMargin costs an order's lines against the thresholds. Reward lines
+// of promotions lower the amount without adding cost; only product lines
+// with a price are held to the minimum line margin.
+func computeMargin(order types.MarginOrder, settings types.MarginSettings) types.QuoteMargin {
+	margin := types.QuoteMargin{
+		OrderID:   order.OrderID,
+		Reference: order.Reference,
+		State:     order.State,
+		Lines:     make([]types.MarginLine, 0, len(order.Lines)),
+		Settings:  settings,
+	}
+
+	var amount, cost float64
+	for _, line := range order.Lines {
+		lineCost := line.Quantity * line.UnitCost
+		amount += line.Subtotal
+		cost += lineCost
+
+		line.MarginPercent = marginPercent(line.Subtotal, lineCost)
+		line.CostTotal = roundAmount(lineCost)
+		line.Margin = roundAmount(line.Subtotal - lineCost)
+		line.Subtotal = roundAmount(line.Subtotal)
+		if line.ProductID != nil && line.MarginPercent != nil {
+			if margin.LowestLineMarginPercent == nil || *line.MarginPercent < *margin.LowestLineMarginPercent {
+				lowest := *line.MarginPercent
+				margin.LowestLineMarginPercent = &lowest
+			}
+			line.BelowMinimum = settings.MinLineMarginPercent != nil && *line.MarginPercent < *settings.MinLineMarginPercent
+		}
+		margin.Lines = append(margin.Lines, line)
+	}
+
+	margin.AmountUntaxed = roundAmount(amount)
+	margin.CostTotal = roundAmount(cost)
+	margin.Margin = roundAmount(amount - cost)
+	margin.MarginPercent = marginPercent(amount, cost)
+
+	if settings.MinMarginPercent != nil && percentOrZero(margin.MarginPercent) < *settings.MinMarginPercent {
+		margin.RequiresApproval = true
+	}
+	if settings.MinLineMarginPercent != nil && margin.LowestLineMarginPercent != nil &&
+		*margin.LowestLineMarginPercent < *settings.MinLineMarginPercent {
+		margin.RequiresApproval = true
+	}
+	return margin
+}
+
+// approvalCovers reports whether an approval lets the quote be confirmed:
+// its margins must not have dropped below the ones that were approved
+func approvalCovers(approval types.MarginApproval, margin types.QuoteMargin) bool {
+	if approval.Status != types.MarginApprovalStatusApproved {
+		return false
+	}
+	if percentOrZero(margin.MarginPercent) < percentOrZero(approval.MarginPercent) {
+		return false
+	}
+	if margin.LowestLineMarginPercent != nil && approval.LowestLineMarginPercent != nil &&
+		*margin.LowestLineMarginPercent < *approval.LowestLineMarginPercent {
+		return false
+	}
+	return true
+}
+
+// marginPercent is the margin as a percentage of the amount, or nil when
+// there is no amount to take it from
+func marginPercent(amount, cost float64) *float64 {
+	if amount <= 0 {
+		return nil
+	}
+	percent := math.Round((amount-cost)/amount*10000) / 100
+	return &percent
+}
+
+func percentOrZero(percent *float64) float64 {
+	if percent == nil {
+		return 0
+	}
+	return *percent
+}
+
+// publishEvent publishes an event to the event bus if available
+func (s *MarginService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			// Log error but don't fail the operation
+			fmt.Printf("Failed to publish event %s: %v\n", eventType, err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidPromotion is returned for promotions and coupon codes that
+	// fail validation
+	ErrInvalidPromotion = errors.New("invalid promotion")
+
+	// ErrCouponInUse is returned when another promotion already has the coupon code
+	ErrCouponInUse = repository.ErrCouponInUse
+)
+
+type PromotionService struct {
+	repo repository.PromotionRepository
+}
+
+func NewPromotionService(repo repository.PromotionRepository) *PromotionService {
+	return &PromotionService{
+		repo: repo,
+	}
+}
+
+// ListPromotions lists the organization's promotions by priority
+func (s *PromotionService) ListPromotions(ctx context.Context, orgID uuid.UUID) ([]types.Promotion, error) {
+	promotions, err := s.repo.ListPromotions(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if promotions == nil {
+		promotions = []types.Promotion{}
+	}
+	return promotions, nil
+}
+
+// GetPromotion returns a promotion, or nil when it does not exist
+func (s *PromotionService) GetPromotion(ctx context.Context, orgID, id uuid.UUID) (*types.Promotion, error) {
+	return s.repo.FindPromotion(ctx, orgID, id)
+}
+
+// CreatePromotion validates and creates a promotion
+func (s *PromotionService) CreatePromotion(ctx context.Context, orgID, userID uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	if err := validatePromotion(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreatePromotion(ctx, orgID, userID, request)
+}
+
+// UpdatePromotion validates and replaces a promotion. Orders already priced
+// keep their discount until they are repriced. It returns nil when the
+// promotion does not exist.
+func (s *PromotionService) UpdatePromotion(ctx context.Context, orgID, userID, id uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	if err := validatePromotion(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdatePromotion(ctx, orgID, userID, id, request)
+}
+
+func validatePromotion(request *types.PromotionRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPromotion)
+	}
+	if request.CouponCode != nil {
+		code := normalizeCoupon(*request.CouponCode)
+		if code == "" {
+			request.CouponCode = nil
+		} else {
+			request.CouponCode = &code
+		}
+	}
+
+	switch request.Type {
+	case types.PromotionTypePercentage:
+		if request.Value <= 0 || request.Value > 100 {
+			return fmt.Errorf("%w: a percentage must be between 0 and 100", ErrInvalidPromotion)
+		}
+	case types.PromotionTypeFixed:
+		if request.Value <= 0 {
+			return fmt.Errorf("%w: a fixed discount must be positive", ErrInvalidPromotion)
+		}
+	case types.PromotionTypeBuyXGetY:
+		if request.BuyProductID == nil || *request.BuyProductID == uuid.Nil {
+			return fmt.Errorf("%w: buy_product_id is required for buy X get Y promotions", ErrInvalidPromotion)
+		}
+		if request.BuyQuantity <= 0 || request.RewardQuantity <= 0 {
+			return fmt.Errorf("%w: buy_quantity and reward_quantity must be positive", ErrInvalidPromotion)
+		}
+		request.Value = 0
+		request.ProductIDs = nil
+	default:
+		return fmt.Errorf("%w: promotion_type must be percentage, fixed or buy_x_get_y", ErrInvalidPromotion)
+	}
+
+	if request.MinOrderAmount < 0 {
+		return fmt.Errorf("%w: min_order_amount cannot be negative", ErrInvalidPromotion)
+	}
+	if request.StartsAt != nil && request.EndsAt != nil && !request.StartsAt.Before(*request.EndsAt) {
+		return fmt.Errorf("%w: starts_at must be before ends_at", ErrInvalidPromotion)
+	}
+	if request.UsageLimit != nil && *request.UsageLimit <= 0 {
+		return fmt.Errorf("%w: usage_limit must be positive", ErrInvalidPromotion)
+	}
+	return nil
+}
+
+func normalizeCoupon(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PriceOrder applies the best combination of eligible promotions to a quote
+// or an order that is not confirmed yet, replacing the promotions applied
+// before. Coupon codes entered now must be valid; coupons applied before
+// that no longer qualify are dropped. It returns nil when the order does not
+// exist.
+func (s *PromotionService) PriceOrder(ctx context.Context, orgID, userID, orderID uuid.UUID, request types.PriceOrderRequest) (*types.OrderPricing, error) {
+	order, err := s.repo.FindPricingOrder(ctx, orgID, orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if order.State != "draft" && order.State != "sent" {
+		return nil, fmt.Errorf("%w: only quotations can be repriced", ErrInvalidPromotion)
+	}
+
+	promotions, err := s.repo.ActivePromotions(ctx, orgID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	coupons := make(map[string]bool)
+	for _, code := range order.CouponCodes {
+		coupons[normalizeCoupon(code)] = true
+	}
+	var entered []string
+	for _, code := range request.CouponCodes {
+		code = normalizeCoupon(code)
+		if code != "" && !coupons[code] {
+			coupons[code] = true
+			entered = append(entered, code)
+		}
+	}
+
+	applied, rejected := evaluatePromotions(*order, promotions, coupons)
+	for _, code := range entered {
+		if reason, ok := rejected[code]; ok {
+			return nil, fmt.Errorf("%w: coupon %s %s", ErrInvalidPromotion, code, reason)
+		}
+	}
+
+	return s.repo.ApplyPricing(ctx, orgID, userID, orderID, applied)
+}
+
+// ListOrderPromotions lists the promotions applied to an order
+func (s *PromotionService) ListOrderPromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.AppliedPromotion, error) {
+	applied, err := s.repo.ListOrderPromotions(ctx, orgID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if applied == nil {
+		applied = []types.AppliedPromotion{}
+	}
+	return applied, nil
+}
+
+// Performance reports how each promotion, or only promotionID when set, did
+// on the orders dated in [from, to)
+func (s *PromotionService) Performance(ctx context.Context, orgID uuid.UUID, promotionID *uuid.UUID, from, to *time.Time) ([]types.PromotionPerformance, error) {
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidPromotion)
+	}
+
+	report, err := s.repo.Performance(ctx, orgID, promotionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		report = []types.PromotionPerformance{}
+	}
+	for i := range report {
+		perf := &report[i]
+		if perf.ConfirmedOrders > 0 {
+			perf.AverageOrderValue = roundAmount(perf.Revenue / float64(perf.ConfirmedOrders))
+		}
+		if perf.Orders > 0 {
+			perf.ConversionRate = math.Round(float64(perf.ConfirmedOrders)/float64(perf.Orders)*10000) / 10000
+		}
+	}
+	return report, nil
+}
+
+// evaluatePromotions returns the promotions to apply to the order and, by
+// coupon code, why the coupon promotions that were entered do not apply.
+// Stackable promotions add up, capped at the order subtotal; a promotion
+// that is not stackable is applied alone. The order gets whichever of the
+// two discounts more, stackable promotions winning ties.
+func evaluatePromotions(order types.PricingOrder, promotions []types.Promotion, coupons map[string]bool) ([]types.AppliedPromotion, map[string]string) {
+	rejected := make(map[string]string)
+	for code := range coupons {
+		rejected[code] = "is not valid"
+	}
+
+	var subtotal float64
+	for _, line := range order.Lines {
+		subtotal += line.Subtotal
+	}
+
+	type candidate struct {
+		promotion types.Promotion
+		discount  float64
+	}
+	var candidates []candidate
+	for _, p := range promotions {
+		var code string
+		if p.CouponCode != nil {
+			code = normalizeCoupon(*p.CouponCode)
+			if !coupons[code] {
+				continue
+			}
+		}
+
+		discount, reason := promotionDiscount(order, subtotal, p)
+		if reason != "" {
+			if code != "" {
+				rejected[code] = reason
+			}
+			continue
+		}
+		if code != "" {
+			delete(rejected, code)
+		}
+		candidates = append(candidates, candidate{promotion: p, discount: discount})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].promotion.Priority != candidates[j].promotion.Priority {
+			return candidates[i].promotion.Priority < candidates[j].promotion.Priority
+		}
+		return candidates[i].discount > candidates[j].discount
+	})
+
+	var stacked []types.AppliedPromotion
+	var stackedTotal float64
+	var best *candidate
+	for i, c := range candidates {
+		if !c.promotion.Stackable {
+			if best == nil || c.discount > best.discount {
+				best = &candidates[i]
+			}
+			continue
+		}
+		discount := roundAmount(math.Min(c.discount, subtotal-stackedTotal))
+		if discount <= 0 {
+			continue
+		}
+		stackedTotal += discount
+		stacked = append(stacked, appliedPromotion(c.promotion, discount))
+	}
+
+	if best != nil && best.discount > stackedTotal {
+		return []types.AppliedPromotion{appliedPromotion(best.promotion, best.discount)}, rejected
+	}
+	return stacked, rejected
+}
+
+func appliedPromotion(p types.Promotion, discount float64) types.AppliedPromotion {
+	applied := types.AppliedPromotion{PromotionID: p.ID, Name: p.Name, Discount: discount}
+	if p.CouponCode != nil {
+		code := normalizeCoupon(*p.CouponCode)
+		applied.CouponCode = &code
+	}
+	return applied
+}
+
+// promotionDiscount returns what the promotion takes off the order, or why
+// it does not apply
+func promotionDiscount(order types.PricingOrder, subtotal float64, p types.Promotion) (float64, string) {
+	if !p.Active {
+		return 0, "is not active"
+	}
+	if p.StartsAt != nil && order.OrderDate.Before(*p.StartsAt) {
+		return 0, "is not valid yet"
+	}
+	if p.EndsAt != nil && !order.OrderDate.Before(*p.EndsAt) {
+		return 0, "has expired"
+	}
+	if p.SegmentID != nil && !containsID(order.SegmentIDs, *p.SegmentID) {
+		return 0, "is not available to this customer"
+	}
+	if p.UsageLimit != nil && p.TimesUsed >= *p.UsageLimit {
+		return 0, "has reached its usage limit"
+	}
+	if subtotal < p.MinOrderAmount {
+		return 0, fmt.Sprintf("requires an order of at least %.2f", p.MinOrderAmount)
+	}
+
+	var discount float64
+	switch p.Type {
+	case types.PromotionTypePercentage:
+		discount = eligibleSubtotal(order.Lines, p.ProductIDs) * p.Value / 100
+	case types.PromotionTypeFixed:
+		discount = math.Min(p.Value, eligibleSubtotal(order.Lines, p.ProductIDs))
+	case types.PromotionTypeBuyXGetY:
+		discount = rewardDiscount(order.Lines, p)
+	}
+	discount = roundAmount(discount)
+	if discount <= 0 {
+		return 0, "does not apply to any product on the order"
+	}
+	return discount, ""
+}
+
+// eligibleSubtotal sums the lines of the products, or every line when
+// productIDs is empty
+func eligibleSubtotal(lines []types.PricingLine, productIDs []uuid.UUID) float64 {
+	var total float64
+	for _, line := range lines {
+		if len(productIDs) == 0 || (line.ProductID != nil && containsID(productIDs, *line.ProductID)) {
+			total += line.Subtotal
+		}
+	}
+	return total
+}
+
+// rewardDiscount prices the free units of a buy X get Y promotion at the
+// cheapest reward lines. When the reward is the product bought, the free
+// units are part of the quantity: buy 2 get 1 makes one unit in three free.
+func rewardDiscount(lines []types.PricingLine, p types.Promotion) float64 {
+	if p.BuyProductID == nil || p.BuyQuantity <= 0 || p.RewardQuantity <= 0 {
+		return 0
+	}
+	rewardProductID := *p.BuyProductID
+	if p.RewardProductID != nil {
+		rewardProductID = *p.RewardProductID
+	}
+
+	var bought float64
+	var rewardLines []types.PricingLine
+	for _, line := range lines {
+		if line.ProductID == nil || line.Quantity <= 0 {
+			continue
+		}
+		if *line.ProductID == *p.BuyProductID {
+			bought += line.Quantity
+		}
+		if *line.ProductID == rewardProductID {
+			rewardLines = append(rewardLines, line)
+		}
+	}
+
+	var free float64
+	if rewardProductID == *p.BuyProductID {
+		free = math.Floor(bought/(p.BuyQuantity+p.RewardQuantity)) * p.RewardQuantity
+	} else {
+		free = math.Floor(bought/p.BuyQuantity) * p.RewardQuantity
+	}
+
+	// Line discounts carry over to the free units
+	sort.SliceStable(rewardLines, func(i, j int) bool {
+		return rewardLines[i].Subtotal/rewardLines[i].Quantity < rewardLines[j].Subtotal/rewardLines[j].Quantity
+	})
+	var discount float64
+	for _, line := range rewardLines {
+		if free <= 0 {
+			break
+		}
+		units := math.Min(free, line.Quantity)
+		discount += units * line.Subtotal / line.Quantity
+		free -= units
+	}
+	return discount
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
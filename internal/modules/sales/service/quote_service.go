@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -25,7 +26,7 @@ type QuoteService struct {
 	storage           storage.Storage
 	templateEngine    *templates.Engine
 	pdfGenerator      *templates.PDFGenerator
-	emailService      email.EmailService
+	emailService      email.Service
 	jobQueue          queue.Queue
 	eventBus          *events.Bus
 	config            QuoteConfig
@@ -99,7 +100,7 @@ func NewQuoteService(
 	storage storage.Storage,
 	templateEngine *templates.Engine,
 	pdfGenerator *templates.PDFGenerator,
-	emailService email.EmailService,
+	emailService email.Service,
 	jobQueue queue.Queue,
 	config QuoteConfig,
 ) *QuoteService {
@@ -122,7 +123,7 @@ func NewQuoteServiceWithEventBus(
 	storage storage.Storage,
 	templateEngine *templates.Engine,
 	pdfGenerator *templates.PDFGenerator,
-	emailService email.EmailService,
+	emailService email.Service,
 	jobQueue queue.Queue,
 	config QuoteConfig,
 	eventBus *events.Bus,
@@ -171,6 +172,11 @@ func (s *QuoteService) CreateQuote(ctx context.Context, order types.SalesOrder)
 	return createdOrder, nil
 }
 
+// GetQuote retrieves a quote by ID
+func (s *QuoteService) GetQuote(ctx context.Context, quoteID uuid.UUID) (*types.SalesOrder, error) {
+	return s.salesOrderService.GetSalesOrder(ctx, quoteID)
+}
+
 // GenerateQuotePDF generates a PDF for a quote
 func (s *QuoteService) GenerateQuotePDF(ctx context.Context, quoteID uuid.UUID, template string) ([]byte, error) {
 	// Get the quote
@@ -253,8 +259,9 @@ func (s *QuoteService) SaveQuotePDF(ctx context.Context, quoteID uuid.UUID, temp
 	// Upload to storage
 	metadata, err := s.storage.Upload(ctx, storage.UploadOptions{
 		Key:         storageKey,
-		Data:        pdfBytes,
+		Reader:      bytes.NewReader(pdfBytes),
 		ContentType: "application/pdf",
+		Size:        int64(len(pdfBytes)),
 		Metadata: map[string]string{
 			"quote_id":  quoteID.String(),
 			"generated": time.Now().Format(time.RFC3339),
@@ -304,21 +311,20 @@ func (s *QuoteService) SendQuoteByEmail(ctx context.Context, request QuoteEmailR
 	}
 
 	// Render email body from template
-	emailBody, err := s.templateEngine.RenderString(ctx, "emails/quote_sent", emailData)
+	emailBody, err := s.templateEngine.RenderHTML("emails/quote_sent", emailData)
 	if err != nil {
 		return fmt.Errorf("failed to render email template: %w", err)
 	}
 
 	// Send email
-	emailReq := email.SendEmailRequest{
+	emailReq := &email.Email{
 		To:      []string{request.RecipientEmail},
 		Subject: request.Subject,
-		Body:    emailBody,
-		IsHTML:  true,
+		HTML:    emailBody,
 	}
 
 	if request.AttachPDF {
-		emailReq.Attachments = []email.Attachment{
+		emailReq.Attachments = []*email.Attachment{
 			{
 				Filename:    fmt.Sprintf("quote-%s.pdf", order.Reference),
 				ContentType: "application/pdf",
@@ -352,7 +358,7 @@ func (s *QuoteService) SendQuoteByEmail(ctx context.Context, request QuoteEmailR
 func (s *QuoteService) SendQuoteByEmailAsync(ctx context.Context, request QuoteEmailRequest) error {
 	// Enqueue job
 	job := queue.Job{
-		Type: "quote:send_email",
+		JobType: "quote:send_email",
 		Payload: map[string]interface{}{
 			"quote_id":        request.QuoteID.String(),
 			"recipient_name":  request.RecipientName,
@@ -361,8 +367,8 @@ func (s *QuoteService) SendQuoteByEmailAsync(ctx context.Context, request QuoteE
 			"message":         request.Message,
 			"attach_pdf":      request.AttachPDF,
 		},
-		Priority:   1,
-		MaxRetries: 3,
+		Priority:    1,
+		MaxAttempts: 3,
 	}
 
 	err := s.jobQueue.Enqueue(ctx, job)
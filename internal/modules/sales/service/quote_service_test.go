@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+)
+
+// salesOrders serves one order by ID
+type salesOrders struct {
+	repository.SalesOrderRepository
+	order types.SalesOrder
+}
+
+func (r *salesOrders) FindByID(_ context.Context, id uuid.UUID) (*types.SalesOrder, error) {
+	if id != r.order.ID {
+		return nil, nil
+	}
+	order := r.order
+	return &order, nil
+}
+
+// jobQueue records enqueued jobs; the methods a test does not set panic
+// through the nil embedded interface
+type jobQueue struct {
+	queue.Queue
+	jobs []queue.Job
+}
+
+func (q *jobQueue) Enqueue(_ context.Context, job queue.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestSendQuoteByEmailAsyncEnqueuesJob(t *testing.T) {
+	jobs := &jobQueue{}
+	svc := NewQuoteService(nil, nil, nil, nil, nil, nil, jobs, QuoteConfig{})
+	quoteID := uuid.New()
+
+	err := svc.SendQuoteByEmailAsync(context.Background(), QuoteEmailRequest{
+		QuoteID:        quoteID,
+		RecipientName:  "Jane",
+		RecipientEmail: "jane@example.com",
+		Subject:        "Your quote",
+		AttachPDF:      true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, jobs.jobs, 1)
+	job := jobs.jobs[0]
+	assert.Equal(t, "quote:send_email", job.JobType)
+	assert.Equal(t, 3, job.MaxAttempts)
+	assert.Equal(t, quoteID.String(), job.Payload["quote_id"])
+	assert.Equal(t, "jane@example.com", job.Payload["recipient_email"])
+	assert.Equal(t, true, job.Payload["attach_pdf"])
+}
+
+func TestGetQuoteReadsSalesOrder(t *testing.T) {
+	orders := &salesOrders{order: types.SalesOrder{ID: uuid.New(), Reference: "SO001", Status: types.SalesOrderStatusQuotation}}
+	svc := NewQuoteService(NewSalesOrderService(orders, nil, nil), orders, nil, nil, nil, nil, nil, QuoteConfig{})
+
+	quote, err := svc.GetQuote(context.Background(), orders.order.ID)
+	require.NoError(t, err)
+	require.NotNil(t, quote)
+	assert.Equal(t, "SO001", quote.Reference)
+
+	quote, err = svc.GetQuote(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, quote)
+}
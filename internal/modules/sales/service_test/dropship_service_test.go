@@ -0,0 +1,208 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDropshipRepository is a mock implementation of DropshipRepository
+type MockDropshipRepository struct {
+	mock.Mock
+}
+
+func (m *MockDropshipRepository) SetLineRoute(ctx context.Context, orgID, orderID, lineID uuid.UUID, request types.SalesLineRouteRequest) (*types.SalesLineRouting, error) {
+	args := m.Called(ctx, orgID, orderID, lineID, request)
+	return args.Get(0).(*types.SalesLineRouting), args.Error(1)
+}
+
+func (m *MockDropshipRepository) FindSource(ctx context.Context, orgID, orderID uuid.UUID) (*types.DropshipSource, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).(*types.DropshipSource), args.Error(1)
+}
+
+func (m *MockDropshipRepository) CreateDropship(ctx context.Context, orgID, userID uuid.UUID, source types.DropshipSource, vendorID uuid.UUID, lines []types.DropshipLine) (*types.DropshipOrder, error) {
+	args := m.Called(ctx, orgID, userID, source, vendorID, lines)
+	return args.Get(0).(*types.DropshipOrder), args.Error(1)
+}
+
+func (m *MockDropshipRepository) FindDropship(ctx context.Context, orgID, id uuid.UUID) (*types.DropshipOrder, error) {
+	args := m.Called(ctx, orgID, id)
+	return args.Get(0).(*types.DropshipOrder), args.Error(1)
+}
+
+func (m *MockDropshipRepository) ListDropships(ctx context.Context, orgID, orderID uuid.UUID) ([]types.DropshipOrder, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).([]types.DropshipOrder), args.Error(1)
+}
+
+func (m *MockDropshipRepository) RecordTracking(ctx context.Context, orgID, userID uuid.UUID, dropship types.DropshipOrder, request types.DropshipTrackingRequest) error {
+	args := m.Called(ctx, orgID, userID, dropship, request)
+	return args.Error(0)
+}
+
+func TestDropshipService_CreatePurchaseOrders(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	orderID := uuid.New()
+	vendorA := uuid.New()
+	vendorB := uuid.New()
+
+	t.Run("orders from each vendor once", func(t *testing.T) {
+		lineA1 := types.DropshipLine{SalesLineID: uuid.New(), Quantity: 2, UnitCost: 5, VendorID: &vendorA}
+		lineB := types.DropshipLine{SalesLineID: uuid.New(), Quantity: 1, UnitCost: 9, VendorID: &vendorB}
+		lineA2 := types.DropshipLine{SalesLineID: uuid.New(), Quantity: 3, UnitCost: 4, VendorID: &vendorA}
+		source := &types.DropshipSource{
+			OrderID:    orderID,
+			OrderName:  "SO0042",
+			State:      "sale",
+			CustomerID: uuid.New(),
+			Lines:      []types.DropshipLine{lineA1, lineB, lineA2},
+		}
+
+		repo := new(MockDropshipRepository)
+		repo.On("FindSource", ctx, orgID, orderID).Return(source, nil)
+		repo.On("CreateDropship", ctx, orgID, userID, *source, vendorA, []types.DropshipLine{lineA1, lineA2}).
+			Return(&types.DropshipOrder{ID: uuid.New(), VendorID: vendorA}, nil)
+		repo.On("CreateDropship", ctx, orgID, userID, *source, vendorB, []types.DropshipLine{lineB}).
+			Return(&types.DropshipOrder{ID: uuid.New(), VendorID: vendorB}, nil)
+
+		dropships, err := service.NewDropshipService(repo).CreatePurchaseOrders(ctx, orgID, userID, orderID)
+		require.NoError(t, err)
+		require.Len(t, dropships, 2)
+		assert.Equal(t, vendorA, dropships[0].VendorID)
+		assert.Equal(t, vendorB, dropships[1].VendorID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("requires a confirmed order", func(t *testing.T) {
+		repo := new(MockDropshipRepository)
+		repo.On("FindSource", ctx, orgID, orderID).Return(&types.DropshipSource{
+			OrderID: orderID,
+			State:   "draft",
+			Lines:   []types.DropshipLine{{SalesLineID: uuid.New(), Quantity: 1, VendorID: &vendorA}},
+		}, nil)
+
+		_, err := service.NewDropshipService(repo).CreatePurchaseOrders(ctx, orgID, userID, orderID)
+		assert.ErrorIs(t, err, service.ErrInvalidDropship)
+		repo.AssertNotCalled(t, "CreateDropship", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires pending dropship lines", func(t *testing.T) {
+		repo := new(MockDropshipRepository)
+		repo.On("FindSource", ctx, orgID, orderID).Return(&types.DropshipSource{OrderID: orderID, State: "sale"}, nil)
+
+		_, err := service.NewDropshipService(repo).CreatePurchaseOrders(ctx, orgID, userID, orderID)
+		assert.ErrorIs(t, err, service.ErrInvalidDropship)
+	})
+
+	t.Run("returns nil for unknown orders", func(t *testing.T) {
+		repo := new(MockDropshipRepository)
+		repo.On("FindSource", ctx, orgID, orderID).Return((*types.DropshipSource)(nil), nil)
+
+		dropships, err := service.NewDropshipService(repo).CreatePurchaseOrders(ctx, orgID, userID, orderID)
+		require.NoError(t, err)
+		assert.Nil(t, dropships)
+	})
+}
+
+func TestDropshipService_SetLineRoute(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	orderID := uuid.New()
+	lineID := uuid.New()
+	vendorID := uuid.New()
+	negative := -1.0
+
+	t.Run("stock route clears the vendor", func(t *testing.T) {
+		cost := 3.0
+		expected := types.SalesLineRouteRequest{Route: types.SalesLineRouteStock}
+
+		repo := new(MockDropshipRepository)
+		repo.On("SetLineRoute", ctx, orgID, orderID, lineID, expected).
+			Return(&types.SalesLineRouting{LineID: lineID, Route: types.SalesLineRouteStock}, nil)
+
+		routing, err := service.NewDropshipService(repo).SetLineRoute(ctx, orgID, orderID, lineID,
+			types.SalesLineRouteRequest{Route: types.SalesLineRouteStock, VendorID: &vendorID, UnitCost: &cost})
+		require.NoError(t, err)
+		assert.Equal(t, types.SalesLineRouteStock, routing.Route)
+		repo.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name    string
+		request types.SalesLineRouteRequest
+	}{
+		{"unknown route", types.SalesLineRouteRequest{Route: "mto"}},
+		{"dropship without vendor", types.SalesLineRouteRequest{Route: types.SalesLineRouteDropship}},
+		{"negative cost", types.SalesLineRouteRequest{Route: types.SalesLineRouteDropship, VendorID: &vendorID, UnitCost: &negative}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.NewDropshipService(new(MockDropshipRepository)).SetLineRoute(ctx, orgID, orderID, lineID, tt.request)
+			assert.ErrorIs(t, err, service.ErrInvalidDropship)
+		})
+	}
+}
+
+func TestDropshipService_RecordTracking(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	id := uuid.New()
+
+	t.Run("records the vendor's tracking", func(t *testing.T) {
+		dropship := &types.DropshipOrder{ID: id, PurchaseState: "purchase"}
+		request := types.DropshipTrackingRequest{
+			CarrierName:    "UPS",
+			TrackingNumber: "1Z999",
+			TrackingURL:    "https://track.example.com/1Z999",
+			Status:         "in_transit",
+		}
+
+		repo := new(MockDropshipRepository)
+		repo.On("FindDropship", ctx, orgID, id).Return(dropship, nil)
+		repo.On("RecordTracking", ctx, orgID, userID, *dropship, request).Return(nil)
+
+		result, err := service.NewDropshipService(repo).RecordTracking(ctx, orgID, userID, id, types.DropshipTrackingRequest{
+			CarrierName:    " UPS ",
+			TrackingNumber: "1Z999 ",
+			TrackingURL:    "https://track.example.com/1Z999",
+			Status:         "in_transit",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, id, result.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects cancelled dropships", func(t *testing.T) {
+		repo := new(MockDropshipRepository)
+		repo.On("FindDropship", ctx, orgID, id).Return(&types.DropshipOrder{ID: id, PurchaseState: "cancel"}, nil)
+
+		_, err := service.NewDropshipService(repo).RecordTracking(ctx, orgID, userID, id, types.DropshipTrackingRequest{TrackingNumber: "1Z999"})
+		assert.ErrorIs(t, err, service.ErrInvalidDropship)
+	})
+
+	tests := []struct {
+		name    string
+		request types.DropshipTrackingRequest
+	}{
+		{"no tracking number or status", types.DropshipTrackingRequest{CarrierName: "UPS"}},
+		{"unknown status", types.DropshipTrackingRequest{TrackingNumber: "1Z999", Status: "lost"}},
+		{"tracking url without scheme", types.DropshipTrackingRequest{TrackingNumber: "1Z999", TrackingURL: "track.example.com/1Z999"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.NewDropshipService(new(MockDropshipRepository)).RecordTracking(ctx, orgID, userID, id, tt.request)
+			assert.ErrorIs(t, err, service.ErrInvalidDropship)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockIntercompanyRepository is a mock implementation of IntercompanyRepository
+type MockIntercompanyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIntercompanyRepository) ListPartners(ctx context.Context, orgID uuid.UUID) ([]types.IntercompanyPartner, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]types.IntercompanyPartner), args.Error(1)
+}
+
+func (m *MockIntercompanyRepository) SavePartner(ctx context.Context, orgID uuid.UUID, request types.IntercompanyPartnerRequest) (*types.IntercompanyPartner, error) {
+	args := m.Called(ctx, orgID, request)
+	return args.Get(0).(*types.IntercompanyPartner), args.Error(1)
+}
+
+func (m *MockIntercompanyRepository) FindTrade(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyTrade, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).(*types.IntercompanyTrade), args.Error(1)
+}
+
+func (m *MockIntercompanyRepository) FindInvoice(ctx context.Context, orgID, orderID uuid.UUID) (*types.IntercompanyInvoice, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).(*types.IntercompanyInvoice), args.Error(1)
+}
+
+func (m *MockIntercompanyRepository) CreateInvoices(ctx context.Context, orgID, userID uuid.UUID, trade types.IntercompanyTrade) (*types.IntercompanyInvoice, error) {
+	args := m.Called(ctx, orgID, userID, trade)
+	return args.Get(0).(*types.IntercompanyInvoice), args.Error(1)
+}
+
+func TestIntercompanyService_InvoiceOrder(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	orderID := uuid.New()
+	seller := uuid.New()
+	buyer := uuid.New()
+	sellerPartner := uuid.New()
+
+	trade := func() *types.IntercompanyTrade {
+		return &types.IntercompanyTrade{
+			OrderID:         orderID,
+			OrderName:       "SO0007",
+			State:           "sale",
+			SellerCompanyID: &seller,
+			SellerPartnerID: &sellerPartner,
+			BuyerCompanyID:  &buyer,
+			BuyerPartnerID:  uuid.New(),
+			AmountTotal:     120,
+			Lines:           []types.IntercompanyLine{{Name: "Widget", Quantity: 2, UnitPrice: 50, Subtotal: 100, Total: 120}},
+		}
+	}
+
+	t.Run("invoices both companies", func(t *testing.T) {
+		tr := trade()
+		invoice := &types.IntercompanyInvoice{ID: uuid.New(), SellerCompanyID: seller, BuyerCompanyID: buyer, AmountTotal: 120}
+
+		repo := new(MockIntercompanyRepository)
+		repo.On("FindTrade", ctx, orgID, orderID).Return(tr, nil)
+		repo.On("CreateInvoices", ctx, orgID, userID, *tr).Return(invoice, nil)
+
+		result, err := service.NewIntercompanyService(repo).InvoiceOrder(ctx, orgID, userID, orderID)
+		require.NoError(t, err)
+		assert.Equal(t, invoice.ID, result.ID)
+		repo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name   string
+		modify func(*types.IntercompanyTrade)
+	}{
+		{"unconfirmed order", func(tr *types.IntercompanyTrade) { tr.State = "draft" }},
+		{"external customer", func(tr *types.IntercompanyTrade) { tr.BuyerCompanyID = nil }},
+		{"no selling company", func(tr *types.IntercompanyTrade) { tr.SellerCompanyID = nil }},
+		{"sale to itself", func(tr *types.IntercompanyTrade) { tr.BuyerCompanyID = &seller }},
+		{"seller without partner", func(tr *types.IntercompanyTrade) { tr.SellerPartnerID = nil }},
+		{"no lines", func(tr *types.IntercompanyTrade) { tr.Lines = nil }},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := trade()
+			tt.modify(tr)
+
+			repo := new(MockIntercompanyRepository)
+			repo.On("FindTrade", ctx, orgID, orderID).Return(tr, nil)
+
+			_, err := service.NewIntercompanyService(repo).InvoiceOrder(ctx, orgID, userID, orderID)
+			assert.ErrorIs(t, err, service.ErrInvalidIntercompany)
+			repo.AssertNotCalled(t, "CreateInvoices", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("surfaces already invoiced sales", func(t *testing.T) {
+		tr := trade()
+		repo := new(MockIntercompanyRepository)
+		repo.On("FindTrade", ctx, orgID, orderID).Return(tr, nil)
+		repo.On("CreateInvoices", ctx, orgID, userID, *tr).Return((*types.IntercompanyInvoice)(nil), service.ErrAlreadyInvoiced)
+
+		_, err := service.NewIntercompanyService(repo).InvoiceOrder(ctx, orgID, userID, orderID)
+		assert.ErrorIs(t, err, service.ErrAlreadyInvoiced)
+	})
+}
@@ -0,0 +1,264 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockMarginRepository is a mock implementation of MarginRepository
+type MockMarginRepository struct {
+	mock.Mock
+}
+
+func (m *MockMarginRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.MarginSettings, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(*types.MarginSettings), args.Error(1)
+}
+
+func (m *MockMarginRepository) SaveSettings(ctx context.Context, orgID, userID uuid.UUID, settings types.MarginSettings) (*types.MarginSettings, error) {
+	args := m.Called(ctx, orgID, userID, settings)
+	return args.Get(0).(*types.MarginSettings), args.Error(1)
+}
+
+func (m *MockMarginRepository) FindMarginOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.MarginOrder, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).(*types.MarginOrder), args.Error(1)
+}
+
+func (m *MockMarginRepository) CreateApproval(ctx context.Context, approval types.MarginApproval) (*types.MarginApproval, error) {
+	args := m.Called(ctx, approval)
+	return args.Get(0).(*types.MarginApproval), args.Error(1)
+}
+
+func (m *MockMarginRepository) FindApproval(ctx context.Context, orgID, id uuid.UUID) (*types.MarginApproval, error) {
+	args := m.Called(ctx, orgID, id)
+	return args.Get(0).(*types.MarginApproval), args.Error(1)
+}
+
+func (m *MockMarginRepository) ListApprovals(ctx context.Context, filter types.MarginApprovalFilter) ([]types.MarginApproval, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]types.MarginApproval), args.Error(1)
+}
+
+func (m *MockMarginRepository) ReviewApproval(ctx context.Context, orgID, id, userID uuid.UUID, status types.MarginApprovalStatus, note string) (*types.MarginApproval, error) {
+	args := m.Called(ctx, orgID, id, userID, status, note)
+	return args.Get(0).(*types.MarginApproval), args.Error(1)
+}
+
+func (m *MockMarginRepository) Analytics(ctx context.Context, filter types.MarginAnalyticsFilter, group types.MarginAnalyticsGroup) ([]types.MarginAnalyticsRow, error) {
+	args := m.Called(ctx, filter, group)
+	return args.Get(0).([]types.MarginAnalyticsRow), args.Error(1)
+}
+
+func percent(v float64) *float64 {
+	return &v
+}
+
+func TestMarginService_GetQuoteMargin(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	orderID := uuid.New()
+	widget := uuid.New()
+	gadget := uuid.New()
+
+	line := func(productID uuid.UUID, quantity, unitPrice, unitCost float64) types.MarginLine {
+		return types.MarginLine{LineID: uuid.New(), ProductID: &productID, Quantity: quantity, UnitPrice: unitPrice, Subtotal: quantity * unitPrice, UnitCost: unitCost}
+	}
+	order := func(lines ...types.MarginLine) *types.MarginOrder {
+		return &types.MarginOrder{OrderID: orderID, Reference: "SO001", State: "draft", Lines: lines}
+	}
+	quoteMargin := func(t *testing.T, o *types.MarginOrder, settings *types.MarginSettings, approvals []types.MarginApproval) *types.QuoteMargin {
+		t.Helper()
+		repo := new(MockMarginRepository)
+		repo.On("FindMarginOrder", ctx, orgID, orderID).Return(o, nil)
+		repo.On("GetSettings", ctx, orgID).Return(settings, nil)
+		repo.On("ListApprovals", ctx, mock.Anything).Return(approvals, nil)
+
+		margin, err := service.NewMarginService(repo).GetQuoteMargin(ctx, orgID, orderID)
+		require.NoError(t, err)
+		require.NotNil(t, margin)
+		return margin
+	}
+
+	t.Run("computes line and quote margins", func(t *testing.T) {
+		margin := quoteMargin(t, order(line(widget, 2, 50, 30), line(gadget, 1, 100, 80)), nil, nil)
+
+		assert.Equal(t, 200.0, margin.AmountUntaxed)
+		assert.Equal(t, 140.0, margin.CostTotal)
+		assert.Equal(t, 60.0, margin.Margin)
+		require.NotNil(t, margin.MarginPercent)
+		assert.Equal(t, 30.0, *margin.MarginPercent)
+		require.Len(t, margin.Lines, 2)
+		assert.Equal(t, 60.0, margin.Lines[0].CostTotal)
+		assert.Equal(t, 40.0, *margin.Lines[0].MarginPercent)
+		assert.Equal(t, 20.0, *margin.Lines[1].MarginPercent)
+		assert.Equal(t, 20.0, *margin.LowestLineMarginPercent)
+		assert.False(t, margin.RequiresApproval)
+	})
+
+	t.Run("reward lines lower the margin without adding cost", func(t *testing.T) {
+		reward := types.MarginLine{LineID: uuid.New(), Name: "Promotion: 10 off", Quantity: 1, UnitPrice: -10, Subtotal: -10, IsReward: true}
+		margin := quoteMargin(t, order(line(widget, 2, 50, 30), reward), nil, nil)
+
+		assert.Equal(t, 90.0, margin.AmountUntaxed)
+		assert.Equal(t, 60.0, margin.CostTotal)
+		assert.Equal(t, 33.33, *margin.MarginPercent)
+		assert.Nil(t, margin.Lines[1].MarginPercent)
+		assert.Equal(t, 40.0, *margin.LowestLineMarginPercent)
+	})
+
+	t.Run("requires approval below the minimum quote margin", func(t *testing.T) {
+		settings := &types.MarginSettings{MinMarginPercent: percent(35)}
+		margin := quoteMargin(t, order(line(widget, 2, 50, 30), line(gadget, 1, 100, 80)), settings, nil)
+
+		assert.True(t, margin.RequiresApproval)
+		assert.False(t, margin.Approved)
+	})
+
+	t.Run("requires approval when a line is below the minimum line margin", func(t *testing.T) {
+		settings := &types.MarginSettings{MinMarginPercent: percent(25), MinLineMarginPercent: percent(25)}
+		margin := quoteMargin(t, order(line(widget, 2, 50, 30), line(gadget, 1, 100, 80)), settings, nil)
+
+		assert.True(t, margin.RequiresApproval)
+		assert.False(t, margin.Lines[0].BelowMinimum)
+		assert.True(t, margin.Lines[1].BelowMinimum)
+	})
+
+	t.Run("an approval covers margins that did not drop", func(t *testing.T) {
+		settings := &types.MarginSettings{MinMarginPercent: percent(35)}
+		approved := types.MarginApproval{ID: uuid.New(), OrderID: orderID, Status: types.MarginApprovalStatusApproved, MarginPercent: percent(30)}
+
+		margin := quoteMargin(t, order(line(widget, 2, 50, 30), line(gadget, 1, 100, 80)), settings, []types.MarginApproval{approved})
+		assert.True(t, margin.RequiresApproval)
+		assert.True(t, margin.Approved)
+		assert.Equal(t, approved.ID, margin.Approval.ID)
+
+		margin = quoteMargin(t, order(line(widget, 2, 50, 30), line(gadget, 1, 100, 90)), settings, []types.MarginApproval{approved})
+		assert.True(t, margin.RequiresApproval)
+		assert.False(t, margin.Approved)
+	})
+}
+
+func TestMarginService_RequestApproval(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	orderID := uuid.New()
+	widget := uuid.New()
+	lowMargin := &types.MarginOrder{OrderID: orderID, State: "sent", Lines: []types.MarginLine{
+		{LineID: uuid.New(), ProductID: &widget, Quantity: 1, UnitPrice: 100, Subtotal: 100, UnitCost: 90},
+	}}
+	settings := &types.MarginSettings{MinMarginPercent: percent(20)}
+
+	t.Run("routes a low-margin quote with its margins", func(t *testing.T) {
+		repo := new(MockMarginRepository)
+		repo.On("FindMarginOrder", ctx, orgID, orderID).Return(lowMargin, nil)
+		repo.On("GetSettings", ctx, orgID).Return(settings, nil)
+		repo.On("ListApprovals", ctx, mock.Anything).Return([]types.MarginApproval{}, nil)
+		repo.On("CreateApproval", ctx, mock.MatchedBy(func(a types.MarginApproval) bool {
+			return a.OrderID == orderID && a.RequestedBy == userID && *a.MarginPercent == 10 &&
+				*a.MinMarginPercent == 20 && a.RequestNote == "Strategic account"
+		})).Return(&types.MarginApproval{ID: uuid.New(), Status: types.MarginApprovalStatusPending}, nil)
+
+		approval, err := service.NewMarginService(repo).RequestApproval(ctx, orgID, userID, orderID, types.MarginApprovalRequest{Note: " Strategic account "})
+		require.NoError(t, err)
+		assert.Equal(t, types.MarginApprovalStatusPending, approval.Status)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects quotes that meet the minimum margins", func(t *testing.T) {
+		repo := new(MockMarginRepository)
+		repo.On("FindMarginOrder", ctx, orgID, orderID).Return(lowMargin, nil)
+		repo.On("GetSettings", ctx, orgID).Return(&types.MarginSettings{MinMarginPercent: percent(5)}, nil)
+		repo.On("ListApprovals", ctx, mock.Anything).Return([]types.MarginApproval{}, nil)
+
+		_, err := service.NewMarginService(repo).RequestApproval(ctx, orgID, userID, orderID, types.MarginApprovalRequest{})
+		assert.ErrorIs(t, err, service.ErrInvalidMargin)
+		repo.AssertNotCalled(t, "CreateApproval", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects confirmed orders", func(t *testing.T) {
+		confirmed := *lowMargin
+		confirmed.State = "sale"
+		repo := new(MockMarginRepository)
+		repo.On("FindMarginOrder", ctx, orgID, orderID).Return(&confirmed, nil)
+		repo.On("GetSettings", ctx, orgID).Return(settings, nil)
+		repo.On("ListApprovals", ctx, mock.Anything).Return([]types.MarginApproval{}, nil)
+
+		_, err := service.NewMarginService(repo).RequestApproval(ctx, orgID, userID, orderID, types.MarginApprovalRequest{})
+		assert.ErrorIs(t, err, service.ErrInvalidMargin)
+	})
+}
+
+func TestMarginService_ReviewApproval(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	requester := uuid.New()
+	reviewer := uuid.New()
+	pending := &types.MarginApproval{ID: uuid.New(), Status: types.MarginApprovalStatusPending, RequestedBy: requester}
+
+	t.Run("approves another user's request", func(t *testing.T) {
+		repo := new(MockMarginRepository)
+		repo.On("FindApproval", ctx, orgID, pending.ID).Return(pending, nil)
+		repo.On("ReviewApproval", ctx, orgID, pending.ID, reviewer, types.MarginApprovalStatusApproved, "ok").
+			Return(&types.MarginApproval{ID: pending.ID, Status: types.MarginApprovalStatusApproved}, nil)
+
+		approval, err := service.NewMarginService(repo).ApproveApproval(ctx, orgID, pending.ID, reviewer, types.MarginApprovalReview{Note: "ok"})
+		require.NoError(t, err)
+		assert.Equal(t, types.MarginApprovalStatusApproved, approval.Status)
+	})
+
+	t.Run("requesters cannot approve their own request", func(t *testing.T) {
+		repo := new(MockMarginRepository)
+		repo.On("FindApproval", ctx, orgID, pending.ID).Return(pending, nil)
+
+		_, err := service.NewMarginService(repo).ApproveApproval(ctx, orgID, pending.ID, requester, types.MarginApprovalReview{})
+		assert.ErrorIs(t, err, service.ErrInvalidMargin)
+	})
+
+	t.Run("rejecting requires a note", func(t *testing.T) {
+		_, err := service.NewMarginService(new(MockMarginRepository)).RejectApproval(ctx, orgID, pending.ID, reviewer, types.MarginApprovalReview{Note: " "})
+		assert.ErrorIs(t, err, service.ErrInvalidMargin)
+	})
+
+	t.Run("reviewed approvals cannot be reviewed again", func(t *testing.T) {
+		approved := *pending
+		approved.Status = types.MarginApprovalStatusApproved
+		repo := new(MockMarginRepository)
+		repo.On("FindApproval", ctx, orgID, pending.ID).Return(&approved, nil)
+
+		_, err := service.NewMarginService(repo).RejectApproval(ctx, orgID, pending.ID, reviewer, types.MarginApprovalReview{Note: "too low"})
+		assert.ErrorIs(t, err, service.ErrMarginApprovalNotPending)
+	})
+}
+
+func TestMarginService_Analytics(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	rep := uuid.New()
+
+	repo := new(MockMarginRepository)
+	filter := types.MarginAnalyticsFilter{OrganizationID: orgID, GroupBy: types.MarginAnalyticsByRep}
+	repo.On("Analytics", ctx, filter, types.MarginAnalyticsGroup("")).
+		Return([]types.MarginAnalyticsRow{{Orders: 3, Revenue: 1000, Cost: 750}}, nil)
+	repo.On("Analytics", ctx, filter, types.MarginAnalyticsByRep).
+		Return([]types.MarginAnalyticsRow{{Key: &rep, Name: "Alex", Orders: 3, Revenue: 1000, Cost: 750}}, nil)
+
+	report, err := service.NewMarginService(repo).Analytics(ctx, types.MarginAnalyticsFilter{OrganizationID: orgID})
+	require.NoError(t, err)
+	assert.Equal(t, 250.0, report.Total.Margin)
+	assert.Equal(t, 25.0, *report.Total.MarginPercent)
+	require.Len(t, report.Rows, 1)
+	assert.Equal(t, 25.0, *report.Rows[0].MarginPercent)
+
+	_, err = service.NewMarginService(repo).Analytics(ctx, types.MarginAnalyticsFilter{OrganizationID: orgID, GroupBy: "team"})
+	assert.ErrorIs(t, err, service.ErrInvalidMargin)
+}
@@ -0,0 +1,322 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/sales/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPromotionRepository is a mock implementation of PromotionRepository
+type MockPromotionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPromotionRepository) ListPromotions(ctx context.Context, orgID uuid.UUID) ([]types.Promotion, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]types.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) FindPromotion(ctx context.Context, orgID, id uuid.UUID) (*types.Promotion, error) {
+	args := m.Called(ctx, orgID, id)
+	return args.Get(0).(*types.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) CreatePromotion(ctx context.Context, orgID, userID uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	args := m.Called(ctx, orgID, userID, request)
+	return args.Get(0).(*types.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) UpdatePromotion(ctx context.Context, orgID, userID, id uuid.UUID, request types.PromotionRequest) (*types.Promotion, error) {
+	args := m.Called(ctx, orgID, userID, id, request)
+	return args.Get(0).(*types.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) FindPricingOrder(ctx context.Context, orgID, orderID uuid.UUID) (*types.PricingOrder, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).(*types.PricingOrder), args.Error(1)
+}
+
+func (m *MockPromotionRepository) ActivePromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.Promotion, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).([]types.Promotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) ApplyPricing(ctx context.Context, orgID, userID, orderID uuid.UUID, applied []types.AppliedPromotion) (*types.OrderPricing, error) {
+	args := m.Called(ctx, orgID, userID, orderID, applied)
+	return args.Get(0).(*types.OrderPricing), args.Error(1)
+}
+
+func (m *MockPromotionRepository) ListOrderPromotions(ctx context.Context, orgID, orderID uuid.UUID) ([]types.AppliedPromotion, error) {
+	args := m.Called(ctx, orgID, orderID)
+	return args.Get(0).([]types.AppliedPromotion), args.Error(1)
+}
+
+func (m *MockPromotionRepository) Performance(ctx context.Context, orgID uuid.UUID, promotionID *uuid.UUID, from, to *time.Time) ([]types.PromotionPerformance, error) {
+	args := m.Called(ctx, orgID, promotionID, from, to)
+	return args.Get(0).([]types.PromotionPerformance), args.Error(1)
+}
+
+func TestPromotionService_PriceOrder(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	orderID := uuid.New()
+	widget := uuid.New()
+	gadget := uuid.New()
+	vip := uuid.New()
+	orderDate := time.Date(2025, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	order := func(lines ...types.PricingLine) *types.PricingOrder {
+		return &types.PricingOrder{
+			OrderID:    orderID,
+			State:      "draft",
+			CustomerID: uuid.New(),
+			OrderDate:  orderDate,
+			Lines:      lines,
+		}
+	}
+	line := func(productID uuid.UUID, quantity, unitPrice float64) types.PricingLine {
+		return types.PricingLine{LineID: uuid.New(), ProductID: &productID, Quantity: quantity, UnitPrice: unitPrice, Subtotal: quantity * unitPrice}
+	}
+	promotion := func(name string, promotionType types.PromotionType, value float64) types.Promotion {
+		return types.Promotion{ID: uuid.New(), Name: name, Type: promotionType, Value: value, Stackable: true, Priority: 10, Active: true}
+	}
+	price := func(t *testing.T, o *types.PricingOrder, promotions []types.Promotion, request types.PriceOrderRequest) ([]types.AppliedPromotion, error) {
+		t.Helper()
+		var applied []types.AppliedPromotion
+		repo := new(MockPromotionRepository)
+		repo.On("FindPricingOrder", ctx, orgID, orderID).Return(o, nil)
+		repo.On("ActivePromotions", ctx, orgID, orderID).Return(promotions, nil)
+		repo.On("ApplyPricing", ctx, orgID, userID, orderID, mock.Anything).
+			Run(func(args mock.Arguments) { applied = args.Get(4).([]types.AppliedPromotion) }).
+			Return(&types.OrderPricing{OrderID: orderID}, nil)
+
+		_, err := service.NewPromotionService(repo).PriceOrder(ctx, orgID, userID, orderID, request)
+		return applied, err
+	}
+
+	t.Run("stacks percentage and fixed promotions", func(t *testing.T) {
+		tenOff := promotion("10% off", types.PromotionTypePercentage, 10)
+		fiveOff := promotion("5 off", types.PromotionTypeFixed, 5)
+
+		applied, err := price(t, order(line(widget, 2, 50), line(gadget, 1, 100)), []types.Promotion{tenOff, fiveOff}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+		assert.Equal(t, 20.0, applied[0].Discount)
+		assert.Equal(t, 5.0, applied[1].Discount)
+	})
+
+	t.Run("restricts discounts to the promotion's products", func(t *testing.T) {
+		widgets := promotion("Widgets 25% off", types.PromotionTypePercentage, 25)
+		widgets.ProductIDs = []uuid.UUID{widget}
+
+		applied, err := price(t, order(line(widget, 2, 50), line(gadget, 1, 100)), []types.Promotion{widgets}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, 25.0, applied[0].Discount)
+	})
+
+	t.Run("applies the best promotion that is not stackable alone", func(t *testing.T) {
+		tenOff := promotion("10% off", types.PromotionTypePercentage, 10)
+		fiveOff := promotion("5 off", types.PromotionTypeFixed, 5)
+		clearance := promotion("Clearance", types.PromotionTypePercentage, 30)
+		clearance.Stackable = false
+
+		applied, err := price(t, order(line(widget, 2, 50)), []types.Promotion{tenOff, fiveOff, clearance}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, clearance.ID, applied[0].PromotionID)
+		assert.Equal(t, 30.0, applied[0].Discount)
+	})
+
+	t.Run("caps stacked discounts at the order subtotal", func(t *testing.T) {
+		first := promotion("30 off", types.PromotionTypeFixed, 30)
+		first.Priority = 1
+		second := promotion("40 off", types.PromotionTypeFixed, 40)
+
+		applied, err := price(t, order(line(widget, 1, 50)), []types.Promotion{second, first}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+		assert.Equal(t, first.ID, applied[0].PromotionID)
+		assert.Equal(t, 30.0, applied[0].Discount)
+		assert.Equal(t, 20.0, applied[1].Discount)
+	})
+
+	t.Run("buy two get one free on the same product", func(t *testing.T) {
+		b2g1 := promotion("Buy 2 get 1", types.PromotionTypeBuyXGetY, 0)
+		b2g1.BuyProductID, b2g1.BuyQuantity, b2g1.RewardQuantity = &widget, 2, 1
+
+		applied, err := price(t, order(line(widget, 7, 10)), []types.Promotion{b2g1}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, 20.0, applied[0].Discount)
+	})
+
+	t.Run("buy X get Y rewards at most the reward units ordered", func(t *testing.T) {
+		bundle := promotion("Gadget with widgets", types.PromotionTypeBuyXGetY, 0)
+		bundle.BuyProductID, bundle.BuyQuantity, bundle.RewardProductID, bundle.RewardQuantity = &widget, 2, &gadget, 1
+
+		applied, err := price(t, order(line(widget, 6, 10), line(gadget, 2, 15)), []types.Promotion{bundle}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, 30.0, applied[0].Discount)
+	})
+
+	t.Run("skips promotions outside their dates, segment, limit or minimum", func(t *testing.T) {
+		future := orderDate.Add(24 * time.Hour)
+		notYet := promotion("Next week", types.PromotionTypePercentage, 10)
+		notYet.StartsAt = &future
+		expired := promotion("Last week", types.PromotionTypePercentage, 10)
+		expired.EndsAt = &orderDate
+		vipOnly := promotion("VIP", types.PromotionTypePercentage, 10)
+		vipOnly.SegmentID = &vip
+		limit := 3
+		exhausted := promotion("First three", types.PromotionTypePercentage, 10)
+		exhausted.UsageLimit, exhausted.TimesUsed = &limit, 3
+		bigOrders := promotion("Big orders", types.PromotionTypeFixed, 10)
+		bigOrders.MinOrderAmount = 500
+
+		applied, err := price(t, order(line(widget, 2, 50)), []types.Promotion{notYet, expired, vipOnly, exhausted, bigOrders}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("applies segment promotions to segment members", func(t *testing.T) {
+		vipOnly := promotion("VIP", types.PromotionTypePercentage, 10)
+		vipOnly.SegmentID = &vip
+		o := order(line(widget, 2, 50))
+		o.SegmentIDs = []uuid.UUID{vip}
+
+		applied, err := price(t, o, []types.Promotion{vipOnly}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, 10.0, applied[0].Discount)
+	})
+
+	t.Run("applies coupon promotions once their code is entered", func(t *testing.T) {
+		code := "SPRING25"
+		coupon := promotion("Spring", types.PromotionTypePercentage, 25)
+		coupon.CouponCode = &code
+
+		applied, err := price(t, order(line(widget, 2, 50)), []types.Promotion{coupon}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+
+		applied, err = price(t, order(line(widget, 2, 50)), []types.Promotion{coupon}, types.PriceOrderRequest{CouponCodes: []string{" spring25 "}})
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, 25.0, applied[0].Discount)
+		require.NotNil(t, applied[0].CouponCode)
+		assert.Equal(t, "SPRING25", *applied[0].CouponCode)
+	})
+
+	t.Run("rejects unknown and ineligible coupons", func(t *testing.T) {
+		code := "BIG10"
+		coupon := promotion("Big", types.PromotionTypeFixed, 10)
+		coupon.CouponCode = &code
+		coupon.MinOrderAmount = 500
+
+		_, err := price(t, order(line(widget, 2, 50)), []types.Promotion{coupon}, types.PriceOrderRequest{CouponCodes: []string{"NOPE"}})
+		assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+
+		_, err = price(t, order(line(widget, 2, 50)), []types.Promotion{coupon}, types.PriceOrderRequest{CouponCodes: []string{"big10"}})
+		assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+		assert.Contains(t, err.Error(), "at least 500.00")
+	})
+
+	t.Run("drops applied coupons that no longer qualify", func(t *testing.T) {
+		code := "BIG10"
+		coupon := promotion("Big", types.PromotionTypeFixed, 10)
+		coupon.CouponCode = &code
+		coupon.MinOrderAmount = 500
+		o := order(line(widget, 2, 50))
+		o.CouponCodes = []string{code}
+
+		applied, err := price(t, o, []types.Promotion{coupon}, types.PriceOrderRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("rejects confirmed orders", func(t *testing.T) {
+		o := order(line(widget, 2, 50))
+		o.State = "sale"
+		repo := new(MockPromotionRepository)
+		repo.On("FindPricingOrder", ctx, orgID, orderID).Return(o, nil)
+
+		_, err := service.NewPromotionService(repo).PriceOrder(ctx, orgID, userID, orderID, types.PriceOrderRequest{})
+		assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+		repo.AssertNotCalled(t, "ApplyPricing", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPromotionService_CreatePromotion(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	productID := uuid.New()
+
+	t.Run("normalizes the coupon code", func(t *testing.T) {
+		code := "  summer10 "
+		request := types.PromotionRequest{Name: "Summer", Type: types.PromotionTypePercentage, Value: 10, CouponCode: &code}
+		repo := new(MockPromotionRepository)
+		repo.On("CreatePromotion", ctx, orgID, userID, mock.MatchedBy(func(r types.PromotionRequest) bool {
+			return r.CouponCode != nil && *r.CouponCode == "SUMMER10"
+		})).Return(&types.Promotion{ID: uuid.New()}, nil)
+
+		_, err := service.NewPromotionService(repo).CreatePromotion(ctx, orgID, userID, request)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	starts := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.Add(-time.Hour)
+	zero := 0
+	invalid := map[string]types.PromotionRequest{
+		"missing name":        {Type: types.PromotionTypeFixed, Value: 5},
+		"unknown type":        {Name: "X", Type: "bogo", Value: 5},
+		"percentage over 100": {Name: "X", Type: types.PromotionTypePercentage, Value: 120},
+		"fixed not positive":  {Name: "X", Type: types.PromotionTypeFixed},
+		"buy X get Y without product": {
+			Name: "X", Type: types.PromotionTypeBuyXGetY, BuyQuantity: 2, RewardQuantity: 1,
+		},
+		"buy X get Y without quantities": {
+			Name: "X", Type: types.PromotionTypeBuyXGetY, BuyProductID: &productID,
+		},
+		"ends before it starts": {Name: "X", Type: types.PromotionTypeFixed, Value: 5, StartsAt: &starts, EndsAt: &ends},
+		"zero usage limit":      {Name: "X", Type: types.PromotionTypeFixed, Value: 5, UsageLimit: &zero},
+	}
+	for name, request := range invalid {
+		t.Run(name, func(t *testing.T) {
+			repo := new(MockPromotionRepository)
+			_, err := service.NewPromotionService(repo).CreatePromotion(ctx, orgID, userID, request)
+			assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+			repo.AssertNotCalled(t, "CreatePromotion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPromotionService_Performance(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	repo := new(MockPromotionRepository)
+	repo.On("Performance", ctx, orgID, (*uuid.UUID)(nil), (*time.Time)(nil), (*time.Time)(nil)).Return([]types.PromotionPerformance{
+		{PromotionID: uuid.New(), Name: "Spring", Orders: 8, ConfirmedOrders: 3, Revenue: 900, DiscountTotal: 100},
+		{PromotionID: uuid.New(), Name: "Unused"},
+	}, nil)
+
+	report, err := service.NewPromotionService(repo).Performance(ctx, orgID, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	assert.Equal(t, 300.0, report[0].AverageOrderValue)
+	assert.Equal(t, 0.375, report[0].ConversionRate)
+	assert.Zero(t, report[1].ConversionRate)
+}
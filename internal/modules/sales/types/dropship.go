@@ -0,0 +1,110 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SalesLineRoute is how a sales order line is fulfilled
+type SalesLineRoute string
+
+const (
+	// SalesLineRouteStock lines are delivered from the organization's stock
+	SalesLineRouteStock SalesLineRoute = "stock"
+	// SalesLineRouteDropship lines are purchased from a vendor who ships them
+	// straight to the customer
+	SalesLineRouteDropship SalesLineRoute = "dropship"
+)
+
+// IsValid reports whether the route is a known route
+func (r SalesLineRoute) IsValid() bool {
+	return r == SalesLineRouteStock || r == SalesLineRouteDropship
+}
+
+// SalesLineRouteRequest sets the route of a sales order line. VendorID is
+// required for dropship lines; UnitCost defaults to the product's cost.
+type SalesLineRouteRequest struct {
+	Route    SalesLineRoute `json:"route"`
+	VendorID *uuid.UUID     `json:"vendor_id,omitempty"`
+	UnitCost *float64       `json:"unit_cost,omitempty"`
+}
+
+// SalesLineRouting is the route of a sales order line
+type SalesLineRouting struct {
+	LineID         uuid.UUID      `json:"line_id" db:"id"`
+	OrderID        uuid.UUID      `json:"order_id" db:"order_id"`
+	Route          SalesLineRoute `json:"route" db:"route"`
+	VendorID       *uuid.UUID     `json:"vendor_id,omitempty" db:"dropship_vendor_id"`
+	UnitCost       *float64       `json:"unit_cost,omitempty" db:"dropship_cost"`
+	PurchaseLineID *uuid.UUID     `json:"purchase_line_id,omitempty" db:"purchase_line_id"`
+}
+
+// DropshipLine is a dropship sales order line
+type DropshipLine struct {
+	SalesLineID    uuid.UUID  `json:"sales_line_id" db:"id"`
+	ProductID      *uuid.UUID `json:"product_id,omitempty" db:"product_id"`
+	Name           string     `json:"name" db:"name"`
+	Quantity       float64    `json:"quantity" db:"product_uom_qty"`
+	UnitCost       float64    `json:"unit_cost" db:"unit_cost"`
+	VendorID       *uuid.UUID `json:"vendor_id,omitempty" db:"dropship_vendor_id"`
+	PurchaseLineID *uuid.UUID `json:"purchase_line_id,omitempty" db:"purchase_line_id"`
+}
+
+// DropshipSource is a sales order with the dropship lines that have not been
+// ordered from their vendors yet
+type DropshipSource struct {
+	OrderID    uuid.UUID      `json:"order_id"`
+	OrderName  string         `json:"order_name"`
+	State      string         `json:"state"`
+	CompanyID  *uuid.UUID     `json:"company_id,omitempty"`
+	CurrencyID *uuid.UUID     `json:"currency_id,omitempty"`
+	CustomerID uuid.UUID      `json:"customer_id"`
+	Lines      []DropshipLine `json:"lines"`
+}
+
+// DropshipOrder is a purchase order for dropship lines, shipped by the
+// vendor to the customer
+type DropshipOrder struct {
+	ID                uuid.UUID      `json:"id" db:"id"`
+	OrganizationID    uuid.UUID      `json:"organization_id" db:"organization_id"`
+	SalesOrderID      uuid.UUID      `json:"sales_order_id" db:"sales_order_id"`
+	SalesOrderName    string         `json:"sales_order_name" db:"sales_order_name"`
+	PurchaseOrderID   uuid.UUID      `json:"purchase_order_id" db:"purchase_order_id"`
+	PurchaseOrderName string         `json:"purchase_order_name" db:"purchase_order_name"`
+	PurchaseState     string         `json:"purchase_state" db:"purchase_state"`
+	VendorID          uuid.UUID      `json:"vendor_id" db:"vendor_id"`
+	VendorName        string         `json:"vendor_name" db:"vendor_name"`
+	CustomerID        uuid.UUID      `json:"customer_id" db:"customer_id"`
+	AmountTotal       float64        `json:"amount_total" db:"amount_total"`
+	PickingID         *uuid.UUID     `json:"picking_id,omitempty" db:"picking_id"`
+	ShipmentID        *uuid.UUID     `json:"shipment_id,omitempty" db:"shipment_id"`
+	ShipmentStatus    *string        `json:"shipment_status,omitempty" db:"shipment_status"`
+	CarrierName       *string        `json:"carrier_name,omitempty" db:"carrier_name"`
+	TrackingNumber    *string        `json:"tracking_number,omitempty" db:"tracking_number"`
+	TrackingURL       *string        `json:"tracking_url,omitempty" db:"tracking_url"`
+	Lines             []DropshipLine `json:"lines" db:"-"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// DropshipShipmentStatuses are the delivery shipment statuses a vendor can report
+var DropshipShipmentStatuses = map[string]bool{
+	"scheduled":  true,
+	"in_transit": true,
+	"delivered":  true,
+	"failed":     true,
+	"cancelled":  true,
+}
+
+// DropshipTrackingRequest is tracking data reported by the vendor of a
+// dropship order. Status is a delivery shipment status.
+type DropshipTrackingRequest struct {
+	CarrierName    string     `json:"carrier_name"`
+	CarrierCode    string     `json:"carrier_code,omitempty"`
+	TrackingNumber string     `json:"tracking_number"`
+	TrackingURL    string     `json:"tracking_url,omitempty"`
+	Status         string     `json:"status,omitempty"`
+	EventTime      *time.Time `json:"event_time,omitempty"`
+	Message        string     `json:"message,omitempty"`
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IntercompanyPartner is the contact a company of the organization trades
+// under with the organization's other companies
+type IntercompanyPartner struct {
+	CompanyID   uuid.UUID `json:"company_id" db:"company_id"`
+	CompanyName string    `json:"company_name" db:"company_name"`
+	PartnerID   uuid.UUID `json:"partner_id" db:"partner_id"`
+	PartnerName string    `json:"partner_name" db:"partner_name"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// IntercompanyPartnerRequest links a company to its contact
+type IntercompanyPartnerRequest struct {
+	CompanyID uuid.UUID `json:"company_id"`
+	PartnerID uuid.UUID `json:"partner_id"`
+}
+
+// IntercompanyLine is a sales order line invoiced between companies
+type IntercompanyLine struct {
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Name      string     `json:"name"`
+	Quantity  float64    `json:"quantity"`
+	UnitPrice float64    `json:"unit_price"`
+	Subtotal  float64    `json:"subtotal"`
+	Total     float64    `json:"total"`
+}
+
+// IntercompanyTrade is a sales order seen as a trade between two companies.
+// BuyerCompanyID is nil when the customer is not one of the organization's
+// companies; SellerPartnerID is nil when the selling company has no contact.
+type IntercompanyTrade struct {
+	OrderID         uuid.UUID          `json:"order_id"`
+	OrderName       string             `json:"order_name"`
+	State           string             `json:"state"`
+	SellerCompanyID *uuid.UUID         `json:"seller_company_id,omitempty"`
+	SellerPartnerID *uuid.UUID         `json:"seller_partner_id,omitempty"`
+	BuyerCompanyID  *uuid.UUID         `json:"buyer_company_id,omitempty"`
+	BuyerPartnerID  uuid.UUID          `json:"buyer_partner_id"`
+	CurrencyID      *uuid.UUID         `json:"currency_id,omitempty"`
+	AmountUntaxed   float64            `json:"amount_untaxed"`
+	AmountTax       float64            `json:"amount_tax"`
+	AmountTotal     float64            `json:"amount_total"`
+	Lines           []IntercompanyLine `json:"lines"`
+}
+
+// IntercompanyInvoice pairs the customer invoice of the selling company with
+// the vendor bill of the buying company for an intercompany sale
+type IntercompanyInvoice struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	OrganizationID    uuid.UUID  `json:"organization_id" db:"organization_id"`
+	SalesOrderID      uuid.UUID  `json:"sales_order_id" db:"sales_order_id"`
+	SellerCompanyID   uuid.UUID  `json:"seller_company_id" db:"seller_company_id"`
+	BuyerCompanyID    uuid.UUID  `json:"buyer_company_id" db:"buyer_company_id"`
+	CustomerInvoiceID *uuid.UUID `json:"customer_invoice_id,omitempty" db:"customer_invoice_id"`
+	VendorBillID      *uuid.UUID `json:"vendor_bill_id,omitempty" db:"vendor_bill_id"`
+	AmountTotal       float64    `json:"amount_total" db:"amount_total"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy         *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+}
@@ -0,0 +1,168 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MarginSettings are an organization's minimum margins. Quotes below them
+// need an approved margin approval before they are confirmed. A nil
+// threshold is not enforced.
+type MarginSettings struct {
+	MinMarginPercent     *float64   `json:"min_margin_percent"`
+	MinLineMarginPercent *float64   `json:"min_line_margin_percent"`
+	UpdatedAt            *time.Time `json:"updated_at,omitempty"`
+}
+
+// MarginLine is the cost and margin of a quote line
+type MarginLine struct {
+	LineID    uuid.UUID  `json:"line_id"`
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Name      string     `json:"name"`
+	Quantity  float64    `json:"quantity"`
+	UnitPrice float64    `json:"unit_price"`
+	Subtotal  float64    `json:"subtotal"`
+	UnitCost  float64    `json:"unit_cost"`
+	CostTotal float64    `json:"cost_total"`
+	Margin    float64    `json:"margin"`
+	// MarginPercent is nil for lines without a price
+	MarginPercent *float64 `json:"margin_percent,omitempty"`
+	// CostFrozen is set once the order is confirmed; open quotes are costed
+	// at the product's current standard price
+	CostFrozen bool `json:"cost_frozen"`
+	// Reward lines of promotions lower the amount without adding cost
+	IsReward bool `json:"is_reward"`
+	// BelowMinimum reports whether the line is below the minimum line margin
+	BelowMinimum bool `json:"below_minimum"`
+}
+
+// MarginOrder is an order with the lines its margin is computed from
+type MarginOrder struct {
+	OrderID   uuid.UUID    `json:"order_id"`
+	Reference string       `json:"reference"`
+	State     string       `json:"state"`
+	UserID    *uuid.UUID   `json:"user_id,omitempty"`
+	Lines     []MarginLine `json:"lines"`
+}
+
+// QuoteMargin is the margin of a quote against the organization's thresholds
+type QuoteMargin struct {
+	OrderID       uuid.UUID `json:"order_id"`
+	Reference     string    `json:"reference"`
+	State         string    `json:"state"`
+	AmountUntaxed float64   `json:"amount_untaxed"`
+	CostTotal     float64   `json:"cost_total"`
+	Margin        float64   `json:"margin"`
+	MarginPercent *float64  `json:"margin_percent,omitempty"`
+	// LowestLineMarginPercent is the lowest margin of the product lines
+	LowestLineMarginPercent *float64       `json:"lowest_line_margin_percent,omitempty"`
+	Lines                   []MarginLine   `json:"lines"`
+	Settings                MarginSettings `json:"settings"`
+	// RequiresApproval reports whether the quote is below a threshold
+	RequiresApproval bool `json:"requires_approval"`
+	// Approved reports whether an approval covers the current margins
+	Approved bool            `json:"approved"`
+	Approval *MarginApproval `json:"approval,omitempty"`
+}
+
+// MarginApprovalStatus is where a margin approval is in its review
+type MarginApprovalStatus string
+
+const (
+	MarginApprovalStatusPending  MarginApprovalStatus = "pending"
+	MarginApprovalStatusApproved MarginApprovalStatus = "approved"
+	MarginApprovalStatusRejected MarginApprovalStatus = "rejected"
+)
+
+// IsValid reports whether the status is supported
+func (s MarginApprovalStatus) IsValid() bool {
+	return s == MarginApprovalStatusPending || s == MarginApprovalStatusApproved || s == MarginApprovalStatusRejected
+}
+
+// MarginApproval asks for a quote below the minimum margins to be confirmed
+// anyway. It covers the margins the quote had when it was requested.
+type MarginApproval struct {
+	ID                      uuid.UUID            `json:"id"`
+	OrganizationID          uuid.UUID            `json:"organization_id"`
+	OrderID                 uuid.UUID            `json:"order_id"`
+	Reference               string               `json:"reference,omitempty"`
+	Status                  MarginApprovalStatus `json:"status"`
+	AmountUntaxed           float64              `json:"amount_untaxed"`
+	CostTotal               float64              `json:"cost_total"`
+	MarginPercent           *float64             `json:"margin_percent,omitempty"`
+	LowestLineMarginPercent *float64             `json:"lowest_line_margin_percent,omitempty"`
+	MinMarginPercent        *float64             `json:"min_margin_percent,omitempty"`
+	MinLineMarginPercent    *float64             `json:"min_line_margin_percent,omitempty"`
+	RequestNote             string               `json:"request_note,omitempty"`
+	RequestedBy             uuid.UUID            `json:"requested_by"`
+	RequestedAt             time.Time            `json:"requested_at"`
+	ReviewedBy              *uuid.UUID           `json:"reviewed_by,omitempty"`
+	ReviewedAt              *time.Time           `json:"reviewed_at,omitempty"`
+	ReviewNote              string               `json:"review_note,omitempty"`
+}
+
+// MarginApprovalRequest routes a low-margin quote for approval
+type MarginApprovalRequest struct {
+	Note string `json:"note"`
+}
+
+// MarginApprovalReview approves or rejects a pending margin approval
+type MarginApprovalReview struct {
+	Note string `json:"note"`
+}
+
+// MarginApprovalFilter filters margin approvals
+type MarginApprovalFilter struct {
+	OrganizationID uuid.UUID
+	Status         *MarginApprovalStatus
+	OrderID        *uuid.UUID
+	Limit          int
+	Offset         int
+}
+
+// MarginAnalyticsGroup is what margin analytics are broken down by
+type MarginAnalyticsGroup string
+
+const (
+	MarginAnalyticsByRep     MarginAnalyticsGroup = "rep"
+	MarginAnalyticsByProduct MarginAnalyticsGroup = "product"
+)
+
+// IsValid reports whether the group is supported
+func (g MarginAnalyticsGroup) IsValid() bool {
+	return g == MarginAnalyticsByRep || g == MarginAnalyticsByProduct
+}
+
+// MarginAnalyticsFilter selects the confirmed orders dated in [From, To)
+type MarginAnalyticsFilter struct {
+	OrganizationID uuid.UUID
+	GroupBy        MarginAnalyticsGroup
+	From           *time.Time
+	To             *time.Time
+}
+
+// MarginAnalyticsRow is the margin earned by a sales rep or on a product.
+// Orders without a rep are grouped under a nil key.
+type MarginAnalyticsRow struct {
+	Key    *uuid.UUID `json:"key"`
+	Name   string     `json:"name"`
+	Orders int        `json:"orders"`
+	// Quantity is only summed by product
+	Quantity      float64  `json:"quantity,omitempty"`
+	Revenue       float64  `json:"revenue"`
+	Cost          float64  `json:"cost"`
+	Margin        float64  `json:"margin"`
+	MarginPercent *float64 `json:"margin_percent,omitempty"`
+	// ApprovedOrders counts the orders confirmed under a margin approval
+	ApprovedOrders int `json:"approved_orders"`
+}
+
+// MarginAnalytics breaks the margin of confirmed orders down by rep or product
+type MarginAnalytics struct {
+	GroupBy MarginAnalyticsGroup `json:"group_by"`
+	From    *time.Time           `json:"from,omitempty"`
+	To      *time.Time           `json:"to,omitempty"`
+	Total   MarginAnalyticsRow   `json:"total"`
+	Rows    []MarginAnalyticsRow `json:"rows"`
+}
@@ -0,0 +1,142 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromotionType is how a promotion discounts an order
+type PromotionType string
+
+const (
+	// PromotionTypePercentage takes Value percent off the eligible lines
+	PromotionTypePercentage PromotionType = "percentage"
+	// PromotionTypeFixed takes Value off the eligible lines
+	PromotionTypeFixed PromotionType = "fixed"
+	// PromotionTypeBuyXGetY gives RewardQuantity units for every BuyQuantity
+	// units of BuyProductID
+	PromotionTypeBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+// IsValid reports whether the promotion type is supported
+func (t PromotionType) IsValid() bool {
+	return t == PromotionTypePercentage || t == PromotionTypeFixed || t == PromotionTypeBuyXGetY
+}
+
+// Promotion is a discount rule applied to quotes and orders
+type Promotion struct {
+	ID             uuid.UUID     `json:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id"`
+	Name           string        `json:"name"`
+	Description    string        `json:"description,omitempty"`
+	Type           PromotionType `json:"promotion_type"`
+	Value          float64       `json:"value"`
+	CouponCode     *string       `json:"coupon_code,omitempty"`
+	// ProductIDs restricts percentage and fixed promotions to these products;
+	// empty means every product line
+	ProductIDs      []uuid.UUID `json:"product_ids"`
+	MinOrderAmount  float64     `json:"min_order_amount"`
+	BuyProductID    *uuid.UUID  `json:"buy_product_id,omitempty"`
+	BuyQuantity     float64     `json:"buy_quantity,omitempty"`
+	RewardProductID *uuid.UUID  `json:"reward_product_id,omitempty"`
+	RewardQuantity  float64     `json:"reward_quantity,omitempty"`
+	SegmentID       *uuid.UUID  `json:"segment_id,omitempty"`
+	StartsAt        *time.Time  `json:"starts_at,omitempty"`
+	EndsAt          *time.Time  `json:"ends_at,omitempty"`
+	Stackable       bool        `json:"stackable"`
+	Priority        int         `json:"priority"`
+	UsageLimit      *int        `json:"usage_limit,omitempty"`
+	// TimesUsed counts the open and confirmed orders the promotion is applied to
+	TimesUsed int       `json:"times_used"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PromotionRequest creates or replaces a promotion
+type PromotionRequest struct {
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	Type            PromotionType `json:"promotion_type"`
+	Value           float64       `json:"value"`
+	CouponCode      *string       `json:"coupon_code"`
+	ProductIDs      []uuid.UUID   `json:"product_ids"`
+	MinOrderAmount  float64       `json:"min_order_amount"`
+	BuyProductID    *uuid.UUID    `json:"buy_product_id"`
+	BuyQuantity     float64       `json:"buy_quantity"`
+	RewardProductID *uuid.UUID    `json:"reward_product_id"`
+	RewardQuantity  float64       `json:"reward_quantity"`
+	SegmentID       *uuid.UUID    `json:"segment_id"`
+	StartsAt        *time.Time    `json:"starts_at"`
+	EndsAt          *time.Time    `json:"ends_at"`
+	Stackable       *bool         `json:"stackable"`
+	Priority        int           `json:"priority"`
+	UsageLimit      *int          `json:"usage_limit"`
+	Active          *bool         `json:"active"`
+}
+
+// PricingLine is a product line of an order being priced
+type PricingLine struct {
+	LineID    uuid.UUID  `json:"line_id"`
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Quantity  float64    `json:"quantity"`
+	UnitPrice float64    `json:"unit_price"`
+	Subtotal  float64    `json:"subtotal"`
+}
+
+// PricingOrder is a quote or order with what promotions are evaluated against
+type PricingOrder struct {
+	OrderID    uuid.UUID     `json:"order_id"`
+	State      string        `json:"state"`
+	CustomerID uuid.UUID     `json:"customer_id"`
+	OrderDate  time.Time     `json:"order_date"`
+	Lines      []PricingLine `json:"lines"`
+	// SegmentIDs are the segments of the customer and its parent company
+	SegmentIDs []uuid.UUID `json:"segment_ids"`
+	// CouponCodes are the codes of coupon promotions already applied
+	CouponCodes []string `json:"coupon_codes"`
+}
+
+// PriceOrderRequest reprices an order, entering coupon codes on top of the
+// ones already applied
+type PriceOrderRequest struct {
+	CouponCodes []string `json:"coupon_codes"`
+}
+
+// AppliedPromotion is a promotion applied to an order
+type AppliedPromotion struct {
+	PromotionID uuid.UUID  `json:"promotion_id"`
+	Name        string     `json:"name"`
+	CouponCode  *string    `json:"coupon_code,omitempty"`
+	Discount    float64    `json:"discount"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// OrderPricing is the result of pricing an order
+type OrderPricing struct {
+	OrderID       uuid.UUID          `json:"order_id"`
+	Subtotal      float64            `json:"subtotal"`
+	Discount      float64            `json:"discount"`
+	AmountUntaxed float64            `json:"amount_untaxed"`
+	AmountTotal   float64            `json:"amount_total"`
+	Promotions    []AppliedPromotion `json:"promotions"`
+}
+
+// PromotionPerformance summarizes the orders a promotion was applied to
+type PromotionPerformance struct {
+	PromotionID     uuid.UUID `json:"promotion_id"`
+	Name            string    `json:"name"`
+	CouponCode      *string   `json:"coupon_code,omitempty"`
+	Orders          int       `json:"orders"`
+	ConfirmedOrders int       `json:"confirmed_orders"`
+	Customers       int       `json:"customers"`
+	DiscountTotal   float64   `json:"discount_total"`
+	// Revenue is the untaxed amount of the confirmed orders, after discounts
+	Revenue           float64 `json:"revenue"`
+	AverageOrderValue float64 `json:"average_order_value"`
+	// ConversionRate is the share of quotes using the promotion that were confirmed
+	ConversionRate float64 `json:"conversion_rate"`
+	UsageLimit     *int    `json:"usage_limit,omitempty"`
+	RemainingUses  *int    `json:"remaining_uses,omitempty"`
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// SandboxHandler handles HTTP requests for sandbox organizations
+type SandboxHandler struct {
+	service *service.SandboxService
+}
+
+func NewSandboxHandler(service *service.SandboxService) *SandboxHandler {
+	return &SandboxHandler{service: service}
+}
+
+func (h *SandboxHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/sandbox", h.GetSandbox)
+	router.POST("/api/v1/sandbox", h.CreateSandbox)
+	router.POST("/api/v1/sandbox/token", h.IssueToken)
+	router.POST("/api/v1/sandbox/reset", h.Reset)
+}
+
+// GetSandbox handles GET /api/v1/sandbox
+func (h *SandboxHandler) GetSandbox(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	sandbox, err := h.service.GetSandbox(r.Context(), authCtx)
+	if err != nil {
+		writeSandboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sandbox)
+}
+
+// CreateSandbox handles POST /api/v1/sandbox
+func (h *SandboxHandler) CreateSandbox(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	sandbox, err := h.service.CreateSandbox(r.Context(), authCtx)
+	if err != nil {
+		writeSandboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sandbox)
+}
+
+// IssueToken handles POST /api/v1/sandbox/token
+func (h *SandboxHandler) IssueToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := h.service.IssueToken(r.Context(), authCtx)
+	if err != nil {
+		writeSandboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// Reset handles POST /api/v1/sandbox/reset
+func (h *SandboxHandler) Reset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Reset(r.Context(), authCtx)
+	if err != nil {
+		writeSandboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeSandboxError(w http.ResponseWriter, err error) {
+	if entitlements.WriteError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrSandboxNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrSandboxExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrSandboxContext), errors.Is(err, repository.ErrNotSandbox):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/types"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+// ErrNotSandbox is returned when a sandbox-only operation targets a production organization
+var ErrNotSandbox = errors.New("organization is not a sandbox")
+
+// SandboxRepo defines the interface for sandbox repository operations
+type SandboxRepo interface {
+	FindByParent(ctx context.Context, parentOrgID uuid.UUID) (*types.SandboxOrganization, error)
+	FindByID(ctx context.Context, sandboxOrgID uuid.UUID) (*types.SandboxOrganization, error)
+	Create(ctx context.Context, parentOrgID, userID uuid.UUID) (*types.SandboxOrganization, error)
+	Reset(ctx context.Context, sandboxOrgID uuid.UUID) ([]types.TableReset, error)
+}
+
+// SandboxRepository manages sandbox organizations and their data
+type SandboxRepository struct {
+	db *sql.DB
+}
+
+// Ensure SandboxRepository implements SandboxRepo interface
+var _ SandboxRepo = &SandboxRepository{}
+
+func NewSandboxRepository(db *sql.DB) *SandboxRepository {
+	return &SandboxRepository{db: db}
+}
+
+// preservedTables survive a sandbox reset: memberships, companies and the
+// permission configuration an integrator needs to keep testing.
+var preservedTables = []string{
+	"organization_users",
+	"companies",
+	"sequences",
+	"permission_roles",
+	"permission_role_inheritance",
+	"permission_role_templates",
+	"user_role_assignments",
+	"permission_groups",
+	"role_permission_groups",
+	"permission_table_policies",
+	"permission_column_policies",
+	"permission_row_filters",
+	"retention_policies",
+}
+
+const sandboxColumns = `id, sandbox_parent_id, name, slug, created_at, sandbox_last_reset_at`
+
+func scanSandbox(row *sql.Row) (*types.SandboxOrganization, error) {
+	var s types.SandboxOrganization
+	err := row.Scan(&s.ID, &s.ParentID, &s.Name, &s.Slug, &s.CreatedAt, &s.LastResetAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SandboxRepository) FindByParent(ctx context.Context, parentOrgID uuid.UUID) (*types.SandboxOrganization, error) {
+	query := `
+		SELECT ` + sandboxColumns + `
+		FROM organizations
+		WHERE sandbox_parent_id = $1 AND is_sandbox = true AND deleted_at IS NULL
+	`
+
+	sandbox, err := scanSandbox(r.db.QueryRowContext(ctx, query, parentOrgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find sandbox organization: %w", err)
+	}
+
+	return sandbox, nil
+}
+
+func (r *SandboxRepository) FindByID(ctx context.Context, sandboxOrgID uuid.UUID) (*types.SandboxOrganization, error) {
+	query := `
+		SELECT ` + sandboxColumns + `
+		FROM organizations
+		WHERE id = $1 AND is_sandbox = true AND deleted_at IS NULL
+	`
+
+	sandbox, err := scanSandbox(r.db.QueryRowContext(ctx, query, sandboxOrgID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find sandbox organization: %w", err)
+	}
+
+	return sandbox, nil
+}
+
+// Create provisions a sandbox paired with parentOrgID, copying the parent's
+// settings and companies so integrators start from a familiar setup
+func (r *SandboxRepository) Create(ctx context.Context, parentOrgID, userID uuid.UUID) (*types.SandboxOrganization, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO organizations (
+			name, slug, subscription_tier, subscription_status, max_users, max_companies,
+			features, settings, timezone, date_format, language, currency_id,
+			is_sandbox, sandbox_parent_id, created_by, updated_by
+		)
+		SELECT
+			LEFT(name, 245) || ' (Sandbox)', LEFT(slug, 92) || '-sandbox', subscription_tier, subscription_status, max_users, max_companies,
+			features, settings, timezone, date_format, language, currency_id,
+			true, id, $2, $2
+		FROM organizations
+		WHERE id = $1 AND is_sandbox = false AND deleted_at IS NULL
+		RETURNING ` + sandboxColumns
+
+	sandbox, err := scanSandbox(tx.QueryRowContext(ctx, query, parentOrgID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("parent organization not found or is itself a sandbox")
+		}
+		return nil, fmt.Errorf("failed to create sandbox organization: %w", err)
+	}
+
+	companiesQuery := `
+		INSERT INTO companies (
+			organization_id, name, legal_name, email, phone, website, currency_id, is_default,
+			street, street2, city, state_id, zip, country_id, created_by, updated_by
+		)
+		SELECT
+			$2, name, legal_name, email, phone, website, currency_id, is_default,
+			street, street2, city, state_id, zip, country_id, $3, $3
+		FROM companies
+		WHERE organization_id = $1 AND parent_company_id IS NULL AND deleted_at IS NULL
+	`
+
+	if _, err := tx.ExecContext(ctx, companiesQuery, parentOrgID, sandbox.ID, userID); err != nil {
+		return nil, fmt.Errorf("failed to copy companies to sandbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sandbox creation: %w", err)
+	}
+
+	return sandbox, nil
+}
+
+// Reset deletes every organization-scoped row of the sandbox except the
+// preserved tables. Tables are discovered from the schema so new modules are
+// covered automatically. Deletes that hit a foreign key are retried after the
+// tables referencing them have been cleared.
+func (r *SandboxRepository) Reset(ctx context.Context, sandboxOrgID uuid.UUID) ([]types.TableReset, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the organization row and refuse to touch production data
+	var isSandbox bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT is_sandbox FROM organizations WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		sandboxOrgID,
+	).Scan(&isSandbox)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotSandbox
+		}
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+	if !isSandbox {
+		return nil, ErrNotSandbox
+	}
+
+	tables, err := organizationTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resets []types.TableReset
+	pending := tables
+	for len(pending) > 0 {
+		var blocked []string
+		var lastErr error
+
+		for _, table := range pending {
+			deleted, err := deleteOrgRows(ctx, tx, table, sandboxOrgID)
+			if err != nil {
+				var pgErr *pgconn.PgError
+				if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+					// foreign_key_violation: another table still references these rows
+					blocked = append(blocked, table)
+					lastErr = err
+					continue
+				}
+				return nil, fmt.Errorf("failed to reset %s: %w", table, err)
+			}
+			if deleted > 0 {
+				resets = append(resets, types.TableReset{Table: table, RowsDeleted: deleted})
+			}
+		}
+
+		if len(blocked) == len(pending) {
+			return nil, fmt.Errorf("failed to reset sandbox, %d tables still referenced: %w", len(blocked), lastErr)
+		}
+		pending = blocked
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE organizations SET sandbox_last_reset_at = $2, updated_at = NOW() WHERE id = $1`,
+		sandboxOrgID, time.Now(),
+	); err != nil {
+		return nil, fmt.Errorf("failed to record sandbox reset: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sandbox reset: %w", err)
+	}
+
+	return resets, nil
+}
+
+// organizationTables lists the tables in the current schema that carry an
+// organization_id column, excluding the preserved tables
+func organizationTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	query := `
+		SELECT c.table_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema()
+			AND c.column_name = 'organization_id'
+			AND t.table_type = 'BASE TABLE'
+			AND c.table_name <> ALL($1)
+		ORDER BY c.table_name
+	`
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(preservedTables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during table iteration: %w", err)
+	}
+
+	return tables, nil
+}
+
+// deleteOrgRows deletes the organization's rows from one table inside a
+// savepoint so a foreign key failure does not abort the whole transaction
+func deleteOrgRows(ctx context.Context, tx *sql.Tx, table string, orgID uuid.UUID) (int64, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT sandbox_reset"); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE organization_id = $1", pq.QuoteIdentifier(table)),
+		orgID,
+	)
+	if err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT sandbox_reset"); rbErr != nil {
+			return 0, rbErr
+		}
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT sandbox_reset"); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/entitlements"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSandboxNotFound is returned when the organization has no paired sandbox
+	ErrSandboxNotFound = errors.New("sandbox organization not found")
+	// ErrSandboxExists is returned when a paired sandbox has already been created
+	ErrSandboxExists = errors.New("sandbox organization already exists")
+	// ErrSandboxContext is returned when an operation must be called from the production organization
+	ErrSandboxContext = errors.New("operation is not available from a sandbox organization")
+)
+
+// AuthService defines the permission check used by the sandbox service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// TokenIssuer mints access tokens scoped to a sandbox organization
+type TokenIssuer interface {
+	GenerateSandboxAccessToken(userID, sandboxOrgID uuid.UUID, role string, isSuperAdmin bool) (string, error)
+}
+
+// SandboxService manages paired sandbox organizations
+type SandboxService struct {
+	repo         repository.SandboxRepo
+	authService  AuthService
+	tokens       TokenIssuer
+	tokenTTL     time.Duration
+	entitlements entitlements.Checker
+	logger       *slog.Logger
+}
+
+func NewSandboxService(repo repository.SandboxRepo, authService AuthService, tokens TokenIssuer, tokenTTL time.Duration, logger *slog.Logger) *SandboxService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SandboxService{
+		repo:        repo,
+		authService: authService,
+		tokens:      tokens,
+		tokenTTL:    tokenTTL,
+		logger:      logger,
+	}
+}
+
+// SetEntitlements makes creating a sandbox require a plan that includes
+// sandboxes
+func (s *SandboxService) SetEntitlements(checker entitlements.Checker) {
+	s.entitlements = checker
+}
+
+// GetSandbox returns the sandbox for the caller: the paired sandbox when called
+// from production, or the sandbox itself when called from inside one
+func (s *SandboxService) GetSandbox(ctx context.Context, authCtx *auth.AuthContext) (*types.SandboxOrganization, error) {
+	if err := s.authService.CheckPermission(ctx, "sandbox:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.resolve(ctx, authCtx)
+}
+
+// CreateSandbox provisions the sandbox paired with the caller's organization
+func (s *SandboxService) CreateSandbox(ctx context.Context, authCtx *auth.AuthContext) (*types.SandboxOrganization, error) {
+	if err := s.authService.CheckPermission(ctx, "sandbox:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if authCtx.IsSandbox {
+		return nil, ErrSandboxContext
+	}
+	if s.entitlements != nil {
+		if err := s.entitlements.CheckFeature(ctx, authCtx.OrganizationID, entitlements.FeatureSandbox); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.repo.FindByParent(ctx, authCtx.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrSandboxExists
+	}
+
+	sandbox, err := s.repo.Create(ctx, authCtx.OrganizationID, authCtx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Sandbox organization created", "organization_id", authCtx.OrganizationID, "sandbox_id", sandbox.ID)
+	return sandbox, nil
+}
+
+// IssueToken returns an access token for the paired sandbox, carrying the
+// caller's production role
+func (s *SandboxService) IssueToken(ctx context.Context, authCtx *auth.AuthContext) (*types.SandboxTokenResponse, error) {
+	if err := s.authService.CheckPermission(ctx, "sandbox:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if authCtx.IsSandbox {
+		return nil, ErrSandboxContext
+	}
+
+	sandbox, err := s.resolve(ctx, authCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.GenerateSandboxAccessToken(authCtx.UserID, sandbox.ID, authCtx.PrimaryRole(), authCtx.IsSuperAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sandbox token: %w", err)
+	}
+
+	return &types.SandboxTokenResponse{
+		AccessToken:    token,
+		TokenType:      "Bearer",
+		ExpiresIn:      int(s.tokenTTL.Seconds()),
+		OrganizationID: sandbox.ID,
+		IsSandbox:      true,
+	}, nil
+}
+
+// Reset deletes all data in the caller's sandbox
+func (s *SandboxService) Reset(ctx context.Context, authCtx *auth.AuthContext) (*types.SandboxResetResult, error) {
+	if err := s.authService.CheckPermission(ctx, "sandbox:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	sandbox, err := s.resolve(ctx, authCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := s.repo.Reset(ctx, sandbox.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.SandboxResetResult{
+		OrganizationID: sandbox.ID,
+		ResetAt:        time.Now(),
+		Tables:         tables,
+	}
+	for _, t := range tables {
+		result.RowsDeleted += t.RowsDeleted
+	}
+
+	s.logger.Info("Sandbox organization reset", "sandbox_id", sandbox.ID, "user_id", authCtx.UserID, "rows_deleted", result.RowsDeleted)
+	return result, nil
+}
+
+func (s *SandboxService) resolve(ctx context.Context, authCtx *auth.AuthContext) (*types.SandboxOrganization, error) {
+	var sandbox *types.SandboxOrganization
+	var err error
+	if authCtx.IsSandbox {
+		sandbox, err = s.repo.FindByID(ctx, authCtx.OrganizationID)
+	} else {
+		sandbox, err = s.repo.FindByParent(ctx, authCtx.OrganizationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil {
+		return nil, ErrSandboxNotFound
+	}
+	return sandbox, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/sandbox/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSandboxRepo struct {
+	byParent map[uuid.UUID]*types.SandboxOrganization
+	resetIDs []uuid.UUID
+}
+
+func newFakeSandboxRepo() *fakeSandboxRepo {
+	return &fakeSandboxRepo{byParent: make(map[uuid.UUID]*types.SandboxOrganization)}
+}
+
+func (f *fakeSandboxRepo) FindByParent(ctx context.Context, parentOrgID uuid.UUID) (*types.SandboxOrganization, error) {
+	return f.byParent[parentOrgID], nil
+}
+
+func (f *fakeSandboxRepo) FindByID(ctx context.Context, sandboxOrgID uuid.UUID) (*types.SandboxOrganization, error) {
+	for _, s := range f.byParent {
+		if s.ID == sandboxOrgID {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSandboxRepo) Create(ctx context.Context, parentOrgID, userID uuid.UUID) (*types.SandboxOrganization, error) {
+	s := &types.SandboxOrganization{ID: uuid.New(), ParentID: parentOrgID}
+	f.byParent[parentOrgID] = s
+	return s, nil
+}
+
+func (f *fakeSandboxRepo) Reset(ctx context.Context, sandboxOrgID uuid.UUID) ([]types.TableReset, error) {
+	f.resetIDs = append(f.resetIDs, sandboxOrgID)
+	return []types.TableReset{{Table: "leads", RowsDeleted: 4}, {Table: "contacts", RowsDeleted: 2}}, nil
+}
+
+type fakeAuth struct {
+	permErr error
+}
+
+func (f *fakeAuth) CheckPermission(ctx context.Context, permission string) error {
+	return f.permErr
+}
+
+type fakeTokenIssuer struct {
+	orgID uuid.UUID
+	role  string
+}
+
+func (f *fakeTokenIssuer) GenerateSandboxAccessToken(userID, sandboxOrgID uuid.UUID, role string, isSuperAdmin bool) (string, error) {
+	f.orgID = sandboxOrgID
+	f.role = role
+	return "sandbox-token", nil
+}
+
+func productionContext() *auth.AuthContext {
+	return &auth.AuthContext{UserID: uuid.New(), OrganizationID: uuid.New(), Roles: []string{"admin"}}
+}
+
+func TestCreateSandboxOnlyOncePerOrganization(t *testing.T) {
+	repo := newFakeSandboxRepo()
+	svc := NewSandboxService(repo, &fakeAuth{}, &fakeTokenIssuer{}, time.Hour, nil)
+	authCtx := productionContext()
+
+	sandbox, err := svc.CreateSandbox(context.Background(), authCtx)
+	require.NoError(t, err)
+	assert.Equal(t, authCtx.OrganizationID, sandbox.ParentID)
+
+	_, err = svc.CreateSandbox(context.Background(), authCtx)
+	assert.ErrorIs(t, err, ErrSandboxExists)
+}
+
+func TestCreateSandboxRejectedInsideSandbox(t *testing.T) {
+	svc := NewSandboxService(newFakeSandboxRepo(), &fakeAuth{}, &fakeTokenIssuer{}, time.Hour, nil)
+	authCtx := productionContext()
+	authCtx.IsSandbox = true
+
+	_, err := svc.CreateSandbox(context.Background(), authCtx)
+	assert.ErrorIs(t, err, ErrSandboxContext)
+}
+
+func TestIssueTokenCarriesRoleIntoSandbox(t *testing.T) {
+	repo := newFakeSandboxRepo()
+	tokens := &fakeTokenIssuer{}
+	svc := NewSandboxService(repo, &fakeAuth{}, tokens, time.Hour, nil)
+	authCtx := productionContext()
+
+	_, err := svc.IssueToken(context.Background(), authCtx)
+	assert.ErrorIs(t, err, ErrSandboxNotFound)
+
+	sandbox, err := svc.CreateSandbox(context.Background(), authCtx)
+	require.NoError(t, err)
+
+	resp, err := svc.IssueToken(context.Background(), authCtx)
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.ID, resp.OrganizationID)
+	assert.True(t, resp.IsSandbox)
+	assert.Equal(t, 3600, resp.ExpiresIn)
+	assert.Equal(t, sandbox.ID, tokens.orgID)
+	assert.Equal(t, "admin", tokens.role)
+}
+
+func TestResetTargetsSandboxFromEitherSide(t *testing.T) {
+	repo := newFakeSandboxRepo()
+	svc := NewSandboxService(repo, &fakeAuth{}, &fakeTokenIssuer{}, time.Hour, nil)
+	prodCtx := productionContext()
+
+	sandbox, err := svc.CreateSandbox(context.Background(), prodCtx)
+	require.NoError(t, err)
+
+	result, err := svc.Reset(context.Background(), prodCtx)
+	require.NoError(t, err)
+	assert.Equal(t, sandbox.ID, result.OrganizationID)
+	assert.Equal(t, int64(6), result.RowsDeleted)
+
+	sandboxCtx := &auth.AuthContext{UserID: prodCtx.UserID, OrganizationID: sandbox.ID, IsSandbox: true}
+	_, err = svc.Reset(context.Background(), sandboxCtx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []uuid.UUID{sandbox.ID, sandbox.ID}, repo.resetIDs)
+}
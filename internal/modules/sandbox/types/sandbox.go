@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SandboxOrganization is an isolated test organization paired with a production organization
+type SandboxOrganization struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ParentID    uuid.UUID  `json:"parent_organization_id" db:"sandbox_parent_id"`
+	Name        string     `json:"name" db:"name"`
+	Slug        string     `json:"slug" db:"slug"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastResetAt *time.Time `json:"last_reset_at,omitempty" db:"sandbox_last_reset_at"`
+}
+
+// SandboxTokenResponse is an access token scoped to a sandbox organization
+type SandboxTokenResponse struct {
+	AccessToken    string    `json:"access_token"`
+	TokenType      string    `json:"token_type"`
+	ExpiresIn      int       `json:"expires_in"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	IsSandbox      bool      `json:"is_sandbox"`
+}
+
+// TableReset reports how many rows were removed from one table during a reset
+type TableReset struct {
+	Table       string `json:"table"`
+	RowsDeleted int64  `json:"rows_deleted"`
+}
+
+// SandboxResetResult summarizes a sandbox data reset
+type SandboxResetResult struct {
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	ResetAt        time.Time    `json:"reset_at"`
+	Tables         []TableReset `json:"tables"`
+	RowsDeleted    int64        `json:"rows_deleted"`
+}
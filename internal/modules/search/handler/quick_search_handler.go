@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// QuickSearchHandler handles HTTP requests for the command palette search
+type QuickSearchHandler struct {
+	service *service.QuickSearchService
+}
+
+func NewQuickSearchHandler(service *service.QuickSearchService) *QuickSearchHandler {
+	return &QuickSearchHandler{service: service}
+}
+
+func (h *QuickSearchHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/quick-search", h.QuickSearch)
+}
+
+// QuickSearch handles GET /api/v1/quick-search?q=&types=contact,lead&limit=5
+func (h *QuickSearchHandler) QuickSearch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query().Get("q")
+
+	var recordTypes []types.RecordType
+	if typesParam := r.URL.Query().Get("types"); typesParam != "" {
+		for _, t := range strings.Split(typesParam, ",") {
+			rt := types.RecordType(strings.TrimSpace(t))
+			if !rt.IsValid() {
+				http.Error(w, "invalid record type: "+string(rt), http.StatusBadRequest)
+				return
+			}
+			recordTypes = append(recordTypes, rt)
+		}
+	}
+
+	limit := 0
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		val, err := strconv.Atoi(limitParam)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = val
+	}
+
+	response, err := h.service.Search(r.Context(), query, recordTypes, limit)
+	if err != nil {
+		writeQuickSearchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=10")
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeQuickSearchError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, types.ErrInvalidQuickSearch):
+		status = http.StatusBadRequest
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
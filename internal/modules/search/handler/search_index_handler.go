@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// SearchIndexHandler handles HTTP requests for search index health and reindexes
+type SearchIndexHandler struct {
+	service *service.SearchIndexService
+}
+
+func NewSearchIndexHandler(service *service.SearchIndexService) *SearchIndexHandler {
+	return &SearchIndexHandler{service: service}
+}
+
+func (h *SearchIndexHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/search/index/health", h.GetIndexHealth)
+	router.GET("/api/v1/search/reindex", h.ListReindexJobs)
+	router.POST("/api/v1/search/reindex", h.RequestReindex)
+}
+
+// writeSearchIndexError maps service errors to HTTP statuses
+func writeSearchIndexError(w http.ResponseWriter, err error, status int) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		status = http.StatusForbidden
+	case errors.Is(err, repository.ErrReindexQueued):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// GetIndexHealth handles GET /api/v1/search/index/health
+func (h *SearchIndexHandler) GetIndexHealth(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	health, err := h.service.GetIndexHealth(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeSearchIndexError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// ListReindexJobs handles GET /api/v1/search/reindex
+func (h *SearchIndexHandler) ListReindexJobs(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.service.ListReindexJobs(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeSearchIndexError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// RequestReindex handles POST /api/v1/search/reindex
+func (h *SearchIndexHandler) RequestReindex(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.ReindexRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	job, err := h.service.RequestReindex(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeSearchIndexError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/service"
+)
+
+// IndexRunner drains the search index outbox, runs queued reindexes and
+// prunes processed outbox events in the background. Outbox events and
+// reindexes are claimed with row locks, so every instance can run one.
+type IndexRunner struct {
+	indexService *service.SearchIndexService
+	interval     time.Duration
+	logger       *slog.Logger
+}
+
+func NewIndexRunner(indexService *service.SearchIndexService, interval time.Duration, logger *slog.Logger) *IndexRunner {
+	return &IndexRunner{
+		indexService: indexService,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+// Start polls for pending work every interval until ctx is cancelled
+func (r *IndexRunner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.drain(ctx, "Search index processing failed", r.indexService.ProcessNextBatch)
+				r.drain(ctx, "Search reindex failed", r.indexService.ProcessNextReindex)
+				if _, err := r.indexService.PruneOutbox(ctx); err != nil {
+					r.logger.Error("Pruning search index outbox failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// drain processes items until none are pending
+func (r *IndexRunner) drain(ctx context.Context, failure string, next func(context.Context) (bool, error)) {
+	for ctx.Err() == nil {
+		processed, err := next(ctx)
+		if err != nil {
+			r.logger.Error(failure, "error", err)
+			return
+		}
+		if !processed {
+			return
+		}
+	}
+}
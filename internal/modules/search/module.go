@@ -0,0 +1,100 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SearchModule represents the cross-module search module
+type SearchModule struct {
+	quickSearchHandler *handler.QuickSearchHandler
+	searchIndexHandler *handler.SearchIndexHandler
+	searchIndexService *service.SearchIndexService
+	indexRunner        *jobs.IndexRunner
+	logger             *slog.Logger
+}
+
+// NewSearchModule creates a new search module
+func NewSearchModule() *SearchModule {
+	return &SearchModule{}
+}
+
+// Name returns the module name
+func (m *SearchModule) Name() string {
+	return "search"
+}
+
+// Init initializes the search module and starts the background indexer
+func (m *SearchModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "search")
+	m.logger.Info("Initializing search module")
+
+	// Create repositories
+	quickSearchRepo := repository.NewQuickSearchRepository(deps.DB)
+	searchIndexRepo := repository.NewSearchIndexRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	quickSearchService := service.NewQuickSearchService(quickSearchRepo, authAdapter)
+	m.searchIndexService = service.NewSearchIndexService(searchIndexRepo, authAdapter, m.logger)
+
+	// Create handlers
+	m.quickSearchHandler = handler.NewQuickSearchHandler(quickSearchService)
+	m.searchIndexHandler = handler.NewSearchIndexHandler(m.searchIndexService)
+
+	// Create background indexer
+	m.indexRunner = jobs.NewIndexRunner(m.searchIndexService, service.IndexInterval, m.logger)
+	m.indexRunner.Start(ctx)
+
+	m.logger.Info("Search module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers search module routes
+func (m *SearchModule) RegisterRoutes(router interface{}) {
+	if m.quickSearchHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.quickSearchHandler.RegisterRoutes(r)
+			m.searchIndexHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers records entity change events in the search index outbox
+func (m *SearchModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.searchIndexService == nil {
+		return
+	}
+
+	for eventType := range service.IndexedEvents {
+		eventBus.Subscribe(eventType, m.handleChangeEvent)
+	}
+
+	m.logger.Info("Search module event handlers registered")
+}
+
+// handleChangeEvent queues the records changed by the event for indexing.
+// Failures are logged rather than returned so they never fail the change
+// that raised the event; a reindex repairs any document left behind.
+func (m *SearchModule) handleChangeEvent(ctx context.Context, event events.Event) error {
+	if err := m.searchIndexService.HandleEvent(ctx, event.Type, event.Payload); err != nil {
+		m.logger.Error("Failed to queue search index change", "event", event.Type, "error", err)
+	}
+	return nil
+}
+
+// Health checks the health of the search module
+func (m *SearchModule) Health() error {
+	return nil
+}
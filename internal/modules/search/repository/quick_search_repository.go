@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+)
+
+// QuickSearchRepo defines the interface for quick search repository operations
+type QuickSearchRepo interface {
+	Search(ctx context.Context, filter types.QuickSearchFilter) ([]types.QuickSearchResult, error)
+}
+
+// QuickSearchRepository runs prefix searches across record names and numbers
+type QuickSearchRepository struct {
+	db *sql.DB
+}
+
+// Ensure QuickSearchRepository implements QuickSearchRepo interface
+var _ QuickSearchRepo = &QuickSearchRepository{}
+
+func NewQuickSearchRepository(db *sql.DB) *QuickSearchRepository {
+	return &QuickSearchRepository{db: db}
+}
+
+// quickSearchSources maps each record type to the columns used for matching.
+// Every title/reference column listed here is backed by a trigram index.
+// Indexed record types are searched in their search documents, which copy
+// these columns.
+var quickSearchSources = map[types.RecordType]struct {
+	table     string
+	title     string
+	subtitle  string
+	reference string
+}{
+	types.RecordTypeContact:       {table: "contacts", title: "name", subtitle: "city", reference: "email"},
+	types.RecordTypeLead:          {table: "leads", title: "name", subtitle: "contact_name", reference: "email"},
+	types.RecordTypeProduct:       {table: "products", title: "name", subtitle: "barcode", reference: "default_code"},
+	types.RecordTypeSalesOrder:    {table: "sales_orders", title: "name", subtitle: "client_order_ref", reference: "name"},
+	types.RecordTypeInvoice:       {table: "invoices", title: "name", subtitle: "ref", reference: "name"},
+	types.RecordTypePurchaseOrder: {table: "purchase_orders", title: "name", subtitle: "partner_ref", reference: "name"},
+}
+
+// customRecordBranch searches the titles of records of searchable custom
+// entities, using the same parameters as the core record branches
+var customRecordBranch = `(
+			SELECT 'custom_record' AS record_type, r.id, r.title, d.label::text AS subtitle, d.name::text AS reference,
+				similarity(r.title, $3) AS score,
+				r.updated_at
+			FROM custom_entity_records r
+			JOIN custom_entity_definitions d ON d.id = r.entity_id
+			WHERE r.organization_id = $1 AND r.deleted_at IS NULL AND d.searchable = true
+				AND ` + database.ILike("r.title", 2) + `
+			ORDER BY score DESC, r.updated_at DESC
+			LIMIT $4
+		)`
+
+// documentBranch searches the search documents of an indexed record type,
+// using the same parameters as the source record branches
+const documentBranch = `(
+			SELECT record_type, record_id AS id, title, subtitle, reference,
+				GREATEST(similarity(title, $3), COALESCE(similarity(reference, $3), 0)) AS score,
+				source_updated_at AS updated_at
+			FROM search_documents
+			WHERE organization_id = $1 AND record_type = '%[1]s'
+				AND (%[2]s OR %[3]s)
+			ORDER BY (%[2]s) DESC, score DESC, source_updated_at DESC
+			LIMIT $4
+		)`
+
+// Search performs a per-type capped prefix search.
+// Each record type is queried in its own branch of a UNION ALL so the caps
+// are applied before results are merged.
+func (r *QuickSearchRepository) Search(ctx context.Context, filter types.QuickSearchFilter) ([]types.QuickSearchResult, error) {
+	if filter.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	if len(filter.Types) == 0 {
+		return nil, errors.New("at least one record type is required")
+	}
+
+	// $1 = organization, $2 = prefix pattern, $3 = raw query for ranking, $4 = per-type limit
+	var branches []string
+	for _, recordType := range filter.Types {
+		if recordType == types.RecordTypeCustomRecord {
+			branches = append(branches, customRecordBranch)
+			continue
+		}
+		if recordType.IsIndexed() {
+			branches = append(branches, fmt.Sprintf(documentBranch, recordType, database.ILike("title", 2), database.ILike("reference", 2)))
+			continue
+		}
+
+		src, ok := quickSearchSources[recordType]
+		if !ok {
+			return nil, fmt.Errorf("unsupported record type: %s", recordType)
+		}
+
+		branches = append(branches, fmt.Sprintf(`(
+			SELECT '%[1]s' AS record_type, id, COALESCE(%[3]s, '') AS title, %[4]s::text AS subtitle, %[5]s::text AS reference,
+				GREATEST(similarity(%[3]s, $3), COALESCE(similarity(%[5]s, $3), 0)) AS score,
+				updated_at
+			FROM %[2]s
+			WHERE organization_id = $1 AND deleted_at IS NULL
+				AND (%[6]s OR %[7]s)
+			ORDER BY (%[6]s) DESC, score DESC, updated_at DESC
+			LIMIT $4
+		)`, recordType, src.table, src.title, src.subtitle, src.reference,
+			database.ILike(src.title, 2), database.ILike(src.reference, 2)))
+	}
+
+	query := strings.Join(branches, " UNION ALL ") + " ORDER BY score DESC, updated_at DESC"
+
+	rows, err := r.db.QueryContext(ctx, query,
+		filter.OrganizationID,
+		database.LikePattern(filter.Query, database.MatchModePrefix),
+		filter.Query,
+		filter.PerTypeLimit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run quick search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []types.QuickSearchResult
+	for rows.Next() {
+		var result types.QuickSearchResult
+		if err := rows.Scan(
+			&result.Type,
+			&result.ID,
+			&result.Title,
+			&result.Subtitle,
+			&result.Reference,
+			&result.Score,
+			&result.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan quick search result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during quick search iteration: %w", err)
+	}
+
+	return results, nil
+}
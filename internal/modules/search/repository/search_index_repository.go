@@ -0,0 +1,435 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrReindexQueued is returned when the organization already has a reindex
+// queued or running
+var ErrReindexQueued = errors.New("reindex already queued")
+
+// SearchIndexRepo defines the interface for search index repository operations
+type SearchIndexRepo interface {
+	Enqueue(ctx context.Context, change types.IndexChange) error
+	IndexPending(ctx context.Context, limit, maxAttempts int, retryDelay time.Duration) (types.IndexBatch, error)
+	PruneProcessed(ctx context.Context, before time.Time) (int, error)
+	OutboxStats(ctx context.Context, orgID uuid.UUID, maxAttempts int) (types.OutboxStats, error)
+	CountDocuments(ctx context.Context, orgID uuid.UUID) ([]types.DocumentCount, error)
+
+	CreateReindexJob(ctx context.Context, job types.ReindexJob) error
+	ListReindexJobs(ctx context.Context, orgID uuid.UUID, limit int) ([]types.ReindexJob, error)
+	ClaimReindexJob(ctx context.Context, staleBefore time.Time) (*types.ReindexJob, error)
+	UpdateReindexJob(ctx context.Context, job types.ReindexJob) error
+	Reindex(ctx context.Context, orgID uuid.UUID, recordTypes []types.RecordType, startedAt time.Time) (types.ReindexResult, error)
+}
+
+// SearchIndexRepository keeps search documents in step with their records
+type SearchIndexRepository struct {
+	db *sql.DB
+}
+
+// Ensure SearchIndexRepository implements SearchIndexRepo interface
+var _ SearchIndexRepo = &SearchIndexRepository{}
+
+func NewSearchIndexRepository(db *sql.DB) *SearchIndexRepository {
+	return &SearchIndexRepository{db: db}
+}
+
+// Enqueue records a change in the index outbox, one row per record
+func (r *SearchIndexRepository) Enqueue(ctx context.Context, change types.IndexChange) error {
+	if len(change.RecordIDs) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO search_index_events (organization_id, record_type, record_id, event_type)
+		SELECT $1, $2, record_id, $4
+		FROM unnest($3::uuid[]) AS record_id`,
+		change.OrganizationID, change.RecordType, pq.Array(change.RecordIDs), change.EventType,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue search index change: %w", err)
+	}
+	return nil
+}
+
+// IndexPending claims up to limit pending outbox events and refreshes the
+// documents of their records. Each record type is refreshed under its own
+// savepoint: when one fails, its events are pushed back by retryDelay times
+// their attempts and the others are still marked processed.
+func (r *SearchIndexRepository) IndexPending(ctx context.Context, limit, maxAttempts int, retryDelay time.Duration) (types.IndexBatch, error) {
+	var batch types.IndexBatch
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return batch, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, record_type, record_id
+		FROM search_index_events
+		WHERE processed_at IS NULL AND attempts < $2 AND available_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`,
+		limit, maxAttempts,
+	)
+	if err != nil {
+		return batch, fmt.Errorf("failed to claim search index events: %w", err)
+	}
+
+	eventIDs := make(map[types.RecordType][]int64)
+	recordIDs := make(map[types.RecordType][]uuid.UUID)
+	seen := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var eventID int64
+		var recordType types.RecordType
+		var recordID uuid.UUID
+		if err := rows.Scan(&eventID, &recordType, &recordID); err != nil {
+			rows.Close()
+			return batch, fmt.Errorf("failed to scan search index event: %w", err)
+		}
+		batch.Events++
+		eventIDs[recordType] = append(eventIDs[recordType], eventID)
+		if !seen[recordID] {
+			seen[recordID] = true
+			recordIDs[recordType] = append(recordIDs[recordType], recordID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return batch, fmt.Errorf("error iterating search index events: %w", err)
+	}
+	if batch.Events == 0 {
+		return batch, nil
+	}
+
+	// Events of record types no longer indexed are simply marked processed
+	var processed []int64
+	for recordType, ids := range eventIDs {
+		if !recordType.IsIndexed() {
+			processed = append(processed, ids...)
+		}
+	}
+
+	for _, recordType := range types.IndexedRecordTypes {
+		ids := recordIDs[recordType]
+		if len(ids) == 0 {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT refresh_documents`); err != nil {
+			return batch, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		indexed, removed, err := refreshDocuments(ctx, tx, recordType, "id = ANY($2)", "d.record_id = ANY($2)", pq.Array(ids))
+		if err != nil {
+			if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT refresh_documents`); rbErr != nil {
+				return batch, fmt.Errorf("failed to roll back to savepoint: %w", rbErr)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE search_index_events
+				SET attempts = attempts + 1, last_error = $2,
+					available_at = NOW() + (attempts + 1) * $3 * INTERVAL '1 second'
+				WHERE id = ANY($1)`,
+				pq.Array(eventIDs[recordType]), err.Error(), retryDelay.Seconds(),
+			); err != nil {
+				return batch, fmt.Errorf("failed to record search index failure: %w", err)
+			}
+			if batch.Failed == nil {
+				batch.Failed = make(map[types.RecordType]error)
+			}
+			batch.Failed[recordType] = err
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT refresh_documents`); err != nil {
+			return batch, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		batch.Indexed += indexed
+		batch.Removed += removed
+		processed = append(processed, eventIDs[recordType]...)
+	}
+
+	if len(processed) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE search_index_events
+			SET processed_at = NOW(), attempts = attempts + 1, last_error = NULL
+			WHERE id = ANY($1)`,
+			pq.Array(processed),
+		); err != nil {
+			return batch, fmt.Errorf("failed to mark search index events processed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return batch, fmt.Errorf("failed to commit search index batch: %w", err)
+	}
+	return batch, nil
+}
+
+// refreshDocuments rewrites the documents of the records of a type matching
+// sourceScope and removes the documents matching docScope whose record is
+// gone. Both scopes take their value as $2.
+func refreshDocuments(ctx context.Context, tx *sql.Tx, recordType types.RecordType, sourceScope, docScope string, scopeArg interface{}) (int, int, error) {
+	src, ok := quickSearchSources[recordType]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported record type: %s", recordType)
+	}
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO search_documents (organization_id, record_type, record_id, title, subtitle, reference, source_updated_at, indexed_at)
+		SELECT organization_id, $1::varchar, id, COALESCE(%[2]s, ''), %[3]s::text, %[4]s::text, updated_at, NOW()
+		FROM %[1]s
+		WHERE %[5]s AND deleted_at IS NULL
+		ON CONFLICT (record_type, record_id) DO UPDATE SET
+			organization_id = EXCLUDED.organization_id,
+			title = EXCLUDED.title,
+			subtitle = EXCLUDED.subtitle,
+			reference = EXCLUDED.reference,
+			source_updated_at = EXCLUDED.source_updated_at,
+			indexed_at = EXCLUDED.indexed_at`,
+		src.table, src.title, src.subtitle, src.reference, sourceScope),
+		recordType, scopeArg,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to index %s documents: %w", recordType, err)
+	}
+	indexed, _ := result.RowsAffected()
+
+	result, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM search_documents d
+		WHERE d.record_type = $1 AND %[2]s
+			AND NOT EXISTS (SELECT 1 FROM %[1]s s WHERE s.id = d.record_id AND s.deleted_at IS NULL)`,
+		src.table, docScope),
+		recordType, scopeArg,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to remove %s documents: %w", recordType, err)
+	}
+	removed, _ := result.RowsAffected()
+
+	return int(indexed), int(removed), nil
+}
+
+// PruneProcessed deletes outbox events processed before the instant
+func (r *SearchIndexRepository) PruneProcessed(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM search_index_events
+		WHERE processed_at IS NOT NULL AND processed_at < $1`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune search index events: %w", err)
+	}
+	pruned, _ := result.RowsAffected()
+	return int(pruned), nil
+}
+
+// OutboxStats counts the pending and failed outbox events of an organization
+func (r *SearchIndexRepository) OutboxStats(ctx context.Context, orgID uuid.UUID, maxAttempts int) (types.OutboxStats, error) {
+	var stats types.OutboxStats
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE attempts < $2),
+			COUNT(*) FILTER (WHERE attempts >= $2),
+			MIN(created_at) FILTER (WHERE attempts < $2),
+			(SELECT MAX(processed_at) FROM search_index_events
+				WHERE organization_id = $1 AND processed_at IS NOT NULL)
+		FROM search_index_events
+		WHERE organization_id = $1 AND processed_at IS NULL`,
+		orgID, maxAttempts,
+	).Scan(&stats.Pending, &stats.Failed, &stats.OldestPendingAt, &stats.LastProcessedAt)
+	if err != nil {
+		return stats, fmt.Errorf("failed to read search index outbox: %w", err)
+	}
+	return stats, nil
+}
+
+// CountDocuments counts the documents and live records of each indexed type
+func (r *SearchIndexRepository) CountDocuments(ctx context.Context, orgID uuid.UUID) ([]types.DocumentCount, error) {
+	var branches []string
+	for _, recordType := range types.IndexedRecordTypes {
+		branches = append(branches, fmt.Sprintf(`
+			SELECT '%[1]s',
+				(SELECT COUNT(*) FROM search_documents WHERE organization_id = $1 AND record_type = '%[1]s'),
+				(SELECT COUNT(*) FROM %[2]s WHERE organization_id = $1 AND deleted_at IS NULL)`,
+			recordType, quickSearchSources[recordType].table))
+	}
+
+	rows, err := r.db.QueryContext(ctx, strings.Join(branches, " UNION ALL"), orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search documents: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []types.DocumentCount
+	for rows.Next() {
+		var count types.DocumentCount
+		if err := rows.Scan(&count.RecordType, &count.Documents, &count.Records); err != nil {
+			return nil, fmt.Errorf("failed to scan search document count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search document counts: %w", err)
+	}
+	return counts, nil
+}
+
+const reindexJobColumns = `id, organization_id, record_types, status, indexed_count, removed_count, error,
+	requested_by, created_at, updated_at, started_at, finished_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReindexJob(row rowScanner) (*types.ReindexJob, error) {
+	var job types.ReindexJob
+	var recordTypes pq.StringArray
+	err := row.Scan(&job.ID, &job.OrganizationID, &recordTypes, &job.Status, &job.IndexedCount, &job.RemovedCount,
+		&job.Error, &job.RequestedBy, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, recordType := range recordTypes {
+		job.RecordTypes = append(job.RecordTypes, types.RecordType(recordType))
+	}
+	return &job, nil
+}
+
+func recordTypeNames(recordTypes []types.RecordType) pq.StringArray {
+	names := make(pq.StringArray, len(recordTypes))
+	for i, recordType := range recordTypes {
+		names[i] = string(recordType)
+	}
+	return names
+}
+
+func (r *SearchIndexRepository) CreateReindexJob(ctx context.Context, job types.ReindexJob) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO search_reindex_jobs (id, organization_id, record_types, status, requested_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		job.ID, job.OrganizationID, recordTypeNames(job.RecordTypes), job.Status, job.RequestedBy, job.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrReindexQueued
+		}
+		return fmt.Errorf("failed to create reindex job: %w", err)
+	}
+	return nil
+}
+
+func (r *SearchIndexRepository) ListReindexJobs(ctx context.Context, orgID uuid.UUID, limit int) ([]types.ReindexJob, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+reindexJobColumns+`
+		FROM search_reindex_jobs
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		orgID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reindex jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []types.ReindexJob{}
+	for rows.Next() {
+		job, err := scanReindexJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reindex job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reindex jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimReindexJob marks the oldest queued reindex as running and returns it.
+// A reindex runs in one transaction, so one left running since staleBefore
+// changed nothing and is claimed again. It returns nil when there is nothing
+// to do.
+func (r *SearchIndexRepository) ClaimReindexJob(ctx context.Context, staleBefore time.Time) (*types.ReindexJob, error) {
+	query := `
+		UPDATE search_reindex_jobs
+		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM search_reindex_jobs
+			WHERE status = 'queued' OR (status = 'running' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + reindexJobColumns
+
+	job, err := scanReindexJob(r.db.QueryRowContext(ctx, query, staleBefore))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim reindex job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *SearchIndexRepository) UpdateReindexJob(ctx context.Context, job types.ReindexJob) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE search_reindex_jobs
+		SET status = $2, indexed_count = $3, removed_count = $4, error = $5, finished_at = $6, updated_at = NOW()
+		WHERE id = $1`,
+		job.ID, job.Status, job.IndexedCount, job.RemovedCount, job.Error, job.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reindex job: %w", err)
+	}
+	return nil
+}
+
+// Reindex rebuilds the documents of the record types of an organization in
+// one transaction. Outbox events of those types recorded before startedAt,
+// failed ones included, are covered by the rebuild and marked processed.
+func (r *SearchIndexRepository) Reindex(ctx context.Context, orgID uuid.UUID, recordTypes []types.RecordType, startedAt time.Time) (types.ReindexResult, error) {
+	var result types.ReindexResult
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, recordType := range recordTypes {
+		indexed, removed, err := refreshDocuments(ctx, tx, recordType, "organization_id = $2", "d.organization_id = $2", orgID)
+		if err != nil {
+			return result, err
+		}
+		result.Indexed += indexed
+		result.Removed += removed
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE search_index_events
+		SET processed_at = NOW(), last_error = NULL
+		WHERE organization_id = $1 AND record_type = ANY($2) AND processed_at IS NULL AND created_at <= $3`,
+		orgID, recordTypeNames(recordTypes), startedAt,
+	); err != nil {
+		return result, fmt.Errorf("failed to settle search index events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit reindex: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPerTypeLimit is the number of hits returned per record type when unspecified
+	DefaultPerTypeLimit = 5
+	// MaxPerTypeLimit caps the number of hits per record type
+	MaxPerTypeLimit = 20
+	// MinQueryLength is the shortest query that will hit the database
+	MinQueryLength = 2
+	// MaxQueryLength bounds the query to keep trigram matching cheap
+	MaxQueryLength = 100
+
+	quickSearchTimeout  = 500 * time.Millisecond
+	quickSearchCacheTTL = 10 * time.Second
+	quickSearchCacheMax = 1000
+)
+
+// AuthService defines the interface for authentication/authorization
+type AuthService interface {
+	GetOrganizationID(ctx context.Context) (uuid.UUID, error)
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+type cachedSearch struct {
+	results   []types.QuickSearchResult
+	expiresAt time.Time
+}
+
+// QuickSearchService provides command palette ("jump to record") search
+type QuickSearchService struct {
+	repo        repository.QuickSearchRepo
+	authService AuthService
+
+	mu    sync.Mutex
+	cache map[string]cachedSearch
+}
+
+func NewQuickSearchService(repo repository.QuickSearchRepo, authService AuthService) *QuickSearchService {
+	return &QuickSearchService{
+		repo:        repo,
+		authService: authService,
+		cache:       make(map[string]cachedSearch),
+	}
+}
+
+// Search runs a quick search for the current organization
+func (s *QuickSearchService) Search(ctx context.Context, query string, recordTypes []types.RecordType, perTypeLimit int) (*types.QuickSearchResponse, error) {
+	started := time.Now()
+
+	orgID, err := s.authService.GetOrganizationID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	if err := s.authService.CheckPermission(ctx, "search:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", types.ErrInvalidQuickSearch)
+	}
+	if len([]rune(query)) > MaxQueryLength {
+		return nil, fmt.Errorf("%w: query must be at most %d characters", types.ErrInvalidQuickSearch, MaxQueryLength)
+	}
+
+	response := &types.QuickSearchResponse{
+		Query:   query,
+		Results: []types.QuickSearchResult{},
+		Counts:  make(map[types.RecordType]int),
+	}
+
+	if len(recordTypes) == 0 {
+		recordTypes = types.AllRecordTypes
+	}
+	for _, rt := range recordTypes {
+		if !rt.IsValid() {
+			return nil, fmt.Errorf("%w: invalid record type: %s", types.ErrInvalidQuickSearch, rt)
+		}
+	}
+
+	// Too-short queries return an empty palette instead of scanning every record
+	if len([]rune(query)) < MinQueryLength {
+		response.TookMs = time.Since(started).Milliseconds()
+		return response, nil
+	}
+
+	// Record types the user cannot read are left out rather than failing the search
+	readable := make([]types.RecordType, 0, len(recordTypes))
+	for _, rt := range recordTypes {
+		if err := s.authService.CheckPermission(ctx, rt.ReadPermission()); err == nil {
+			readable = append(readable, rt)
+		}
+	}
+	if len(readable) == 0 {
+		response.TookMs = time.Since(started).Milliseconds()
+		return response, nil
+	}
+
+	if perTypeLimit <= 0 {
+		perTypeLimit = DefaultPerTypeLimit
+	}
+	if perTypeLimit > MaxPerTypeLimit {
+		perTypeLimit = MaxPerTypeLimit
+	}
+
+	filter := types.QuickSearchFilter{
+		OrganizationID: orgID,
+		Query:          query,
+		Types:          readable,
+		PerTypeLimit:   perTypeLimit,
+	}
+
+	key := cacheKey(filter)
+	results, ok := s.getCached(key)
+	if !ok {
+		searchCtx, cancel := context.WithTimeout(ctx, quickSearchTimeout)
+		defer cancel()
+
+		results, err = s.repo.Search(searchCtx, filter)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("quick search timed out: %w", err)
+			}
+			return nil, fmt.Errorf("failed to search records: %w", err)
+		}
+		s.setCached(key, results)
+	}
+
+	for _, result := range results {
+		response.Counts[result.Type]++
+	}
+	if results != nil {
+		response.Results = results
+	}
+	response.TookMs = time.Since(started).Milliseconds()
+
+	return response, nil
+}
+
+func cacheKey(filter types.QuickSearchFilter) string {
+	typeNames := make([]string, len(filter.Types))
+	for i, rt := range filter.Types {
+		typeNames[i] = string(rt)
+	}
+	sort.Strings(typeNames)
+
+	return fmt.Sprintf("%s|%s|%s|%d",
+		filter.OrganizationID,
+		strings.ToLower(filter.Query),
+		strings.Join(typeNames, ","),
+		filter.PerTypeLimit,
+	)
+}
+
+func (s *QuickSearchService) getCached(key string) ([]types.QuickSearchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.cache, key)
+		return nil, false
+	}
+	return entry.results, true
+}
+
+func (s *QuickSearchService) setCached(key string, results []types.QuickSearchResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Keep the cache bounded; type-ahead keys are short-lived so a full reset is cheap
+	if len(s.cache) >= quickSearchCacheMax {
+		s.cache = make(map[string]cachedSearch)
+	}
+	s.cache[key] = cachedSearch{
+		results:   results,
+		expiresAt: time.Now().Add(quickSearchCacheTTL),
+	}
+}
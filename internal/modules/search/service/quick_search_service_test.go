@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQuickSearchRepo struct {
+	calls   int
+	filters []types.QuickSearchFilter
+	results []types.QuickSearchResult
+	err     error
+}
+
+func (f *fakeQuickSearchRepo) Search(ctx context.Context, filter types.QuickSearchFilter) ([]types.QuickSearchResult, error) {
+	f.calls++
+	f.filters = append(f.filters, filter)
+	return f.results, f.err
+}
+
+type fakeAuth struct {
+	orgID   uuid.UUID
+	permErr error
+	denied  map[string]bool
+}
+
+func (f *fakeAuth) GetOrganizationID(ctx context.Context) (uuid.UUID, error) {
+	return f.orgID, nil
+}
+
+func (f *fakeAuth) CheckPermission(ctx context.Context, permission string) error {
+	if f.denied[permission] {
+		return errors.New("missing " + permission)
+	}
+	return f.permErr
+}
+
+func TestQuickSearchShortQuerySkipsRepository(t *testing.T) {
+	repo := &fakeQuickSearchRepo{}
+	svc := NewQuickSearchService(repo, &fakeAuth{orgID: uuid.New()})
+
+	resp, err := svc.Search(context.Background(), " a ", nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, repo.calls)
+	assert.Empty(t, resp.Results)
+	assert.Equal(t, "a", resp.Query)
+}
+
+func TestQuickSearchAppliesDefaultsAndCaps(t *testing.T) {
+	orgID := uuid.New()
+	repo := &fakeQuickSearchRepo{}
+	svc := NewQuickSearchService(repo, &fakeAuth{orgID: orgID})
+
+	_, err := svc.Search(context.Background(), "acme", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, repo.filters, 1)
+	assert.Equal(t, orgID, repo.filters[0].OrganizationID)
+	assert.Equal(t, DefaultPerTypeLimit, repo.filters[0].PerTypeLimit)
+	assert.Equal(t, types.AllRecordTypes, repo.filters[0].Types)
+
+	_, err = svc.Search(context.Background(), "acme corp", []types.RecordType{types.RecordTypeLead}, 500)
+	require.NoError(t, err)
+	require.Len(t, repo.filters, 2)
+	assert.Equal(t, MaxPerTypeLimit, repo.filters[1].PerTypeLimit)
+	assert.Equal(t, []types.RecordType{types.RecordTypeLead}, repo.filters[1].Types)
+}
+
+func TestQuickSearchCountsAndCache(t *testing.T) {
+	repo := &fakeQuickSearchRepo{
+		results: []types.QuickSearchResult{
+			{Type: types.RecordTypeContact, ID: uuid.New(), Title: "Acme Inc"},
+			{Type: types.RecordTypeContact, ID: uuid.New(), Title: "Acme Labs"},
+			{Type: types.RecordTypeInvoice, ID: uuid.New(), Title: "INV/ACME/001"},
+		},
+	}
+	svc := NewQuickSearchService(repo, &fakeAuth{orgID: uuid.New()})
+
+	resp, err := svc.Search(context.Background(), "Acme", nil, 5)
+	require.NoError(t, err)
+	assert.Len(t, resp.Results, 3)
+	assert.Equal(t, 2, resp.Counts[types.RecordTypeContact])
+	assert.Equal(t, 1, resp.Counts[types.RecordTypeInvoice])
+
+	// Same query with different casing is served from cache
+	_, err = svc.Search(context.Background(), "acme", nil, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.calls)
+}
+
+func TestQuickSearchDropsUnreadableRecordTypes(t *testing.T) {
+	repo := &fakeQuickSearchRepo{}
+	auth := &fakeAuth{orgID: uuid.New(), denied: map[string]bool{
+		types.RecordTypeInvoice.ReadPermission():    true,
+		types.RecordTypeSalesOrder.ReadPermission(): true,
+	}}
+	svc := NewQuickSearchService(repo, auth)
+
+	_, err := svc.Search(context.Background(), "acme", nil, 5)
+	require.NoError(t, err)
+	require.Len(t, repo.filters, 1)
+	assert.NotContains(t, repo.filters[0].Types, types.RecordTypeInvoice)
+	assert.NotContains(t, repo.filters[0].Types, types.RecordTypeSalesOrder)
+	assert.Contains(t, repo.filters[0].Types, types.RecordTypeContact)
+
+	// Nothing readable means nothing to search
+	resp, err := svc.Search(context.Background(), "acme", []types.RecordType{types.RecordTypeInvoice}, 5)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Results)
+	assert.Equal(t, 1, repo.calls)
+}
+
+func TestQuickSearchErrors(t *testing.T) {
+	repo := &fakeQuickSearchRepo{err: errors.New("boom")}
+	svc := NewQuickSearchService(repo, &fakeAuth{orgID: uuid.New()})
+
+	_, err := svc.Search(context.Background(), "acme", []types.RecordType{"unknown"}, 5)
+	assert.ErrorIs(t, err, types.ErrInvalidQuickSearch)
+
+	_, err = svc.Search(context.Background(), "  ", nil, 5)
+	assert.ErrorIs(t, err, types.ErrInvalidQuickSearch)
+
+	_, err = svc.Search(context.Background(), "acme", nil, 5)
+	assert.ErrorContains(t, err, "failed to search records")
+
+	denied := NewQuickSearchService(repo, &fakeAuth{orgID: uuid.New(), permErr: errors.New("nope")})
+	_, err = denied.Search(context.Background(), "acme", nil, 5)
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// IndexInterval is how often the index outbox and queued reindexes are picked up
+	IndexInterval = 10 * time.Second
+	// IndexBatchSize caps the outbox events refreshed in one transaction
+	IndexBatchSize = 500
+	// MaxIndexAttempts is how many times an outbox event is tried before it
+	// is left for a reindex to repair
+	MaxIndexAttempts = 5
+	// ReindexStaleAfter is how long a reindex may run before it is started over
+	ReindexStaleAfter = 30 * time.Minute
+	// OutboxRetention is how long processed outbox events are kept
+	OutboxRetention = 7 * 24 * time.Hour
+	// MaxHealthyLag is the age of the oldest pending event past which the
+	// index is reported unhealthy
+	MaxHealthyLag = 5 * time.Minute
+
+	indexRetryDelay     = 30 * time.Second
+	reindexHistoryLimit = 20
+)
+
+// IndexedEvents maps the entity change events the index consumes to the
+// record type they change
+var IndexedEvents = map[string]types.RecordType{
+	"contact.created":       types.RecordTypeContact,
+	"contact.updated":       types.RecordTypeContact,
+	"contact.deleted":       types.RecordTypeContact,
+	"crm.lead.created":      types.RecordTypeLead,
+	"crm.lead.updated":      types.RecordTypeLead,
+	"crm.lead.deleted":      types.RecordTypeLead,
+	"crm.lead.bulk_updated": types.RecordTypeLead,
+	"crm.lead.bulk_deleted": types.RecordTypeLead,
+	"product.created":       types.RecordTypeProduct,
+	"product.updated":       types.RecordTypeProduct,
+	"product.deleted":       types.RecordTypeProduct,
+	"order.created":         types.RecordTypeSalesOrder,
+	"order.updated":         types.RecordTypeSalesOrder,
+	"order.deleted":         types.RecordTypeSalesOrder,
+	"order.confirmed":       types.RecordTypeSalesOrder,
+	"order.cancelled":       types.RecordTypeSalesOrder,
+	"invoice.created":       types.RecordTypeInvoice,
+	"invoice.updated":       types.RecordTypeInvoice,
+	"invoice.deleted":       types.RecordTypeInvoice,
+	"invoice.confirmed":     types.RecordTypeInvoice,
+	"invoice.cancelled":     types.RecordTypeInvoice,
+	"invoice.paid":          types.RecordTypeInvoice,
+}
+
+// changePayload holds the fields read from an entity change event. Events
+// of single records carry the record itself or its id; bulk lead events
+// carry lead_ids.
+type changePayload struct {
+	ID             uuid.UUID   `json:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	LeadIDs        []uuid.UUID `json:"lead_ids"`
+}
+
+// ChangeFromEvent reads the records changed by an entity change event
+func ChangeFromEvent(eventType string, payload interface{}) (types.IndexChange, error) {
+	recordType, ok := IndexedEvents[eventType]
+	if !ok {
+		return types.IndexChange{}, fmt.Errorf("event %s is not indexed", eventType)
+	}
+
+	// The payload might be the struct itself or a map, depending on how it was published
+	var change changePayload
+	bytes, err := json.Marshal(payload)
+	if err == nil {
+		err = json.Unmarshal(bytes, &change)
+	}
+	if err != nil {
+		return types.IndexChange{}, fmt.Errorf("failed to decode %s event: %w", eventType, err)
+	}
+	if change.OrganizationID == uuid.Nil {
+		return types.IndexChange{}, fmt.Errorf("%s event has no organization", eventType)
+	}
+
+	recordIDs := change.LeadIDs
+	if change.ID != uuid.Nil {
+		recordIDs = append([]uuid.UUID{change.ID}, recordIDs...)
+	}
+	if len(recordIDs) == 0 {
+		return types.IndexChange{}, fmt.Errorf("%s event has no record", eventType)
+	}
+
+	return types.IndexChange{
+		OrganizationID: change.OrganizationID,
+		RecordType:     recordType,
+		RecordIDs:      recordIDs,
+		EventType:      eventType,
+	}, nil
+}
+
+// SearchIndexService keeps the search documents behind quick search up to
+// date. Entity change events are recorded in an outbox that a background
+// runner drains, so indexing never slows down or fails the change itself.
+type SearchIndexService struct {
+	repo        repository.SearchIndexRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewSearchIndexService(repo repository.SearchIndexRepo, authService AuthService, logger *slog.Logger) *SearchIndexService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SearchIndexService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// HandleEvent records the records changed by an entity change event in the
+// index outbox
+func (s *SearchIndexService) HandleEvent(ctx context.Context, eventType string, payload interface{}) error {
+	change, err := ChangeFromEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+	return s.repo.Enqueue(ctx, change)
+}
+
+// ProcessNextBatch refreshes the documents of a batch of outbox events. It
+// reports whether there were any, so the runner keeps going until the
+// outbox is drained. Record types that fail are logged; their events are
+// held back and retried later.
+func (s *SearchIndexService) ProcessNextBatch(ctx context.Context) (bool, error) {
+	batch, err := s.repo.IndexPending(ctx, IndexBatchSize, MaxIndexAttempts, indexRetryDelay)
+	if err != nil {
+		return false, err
+	}
+	for recordType, failure := range batch.Failed {
+		s.logger.Error("search index refresh failed", "record_type", recordType, "error", failure)
+	}
+	if batch.Events > 0 {
+		s.logger.Debug("search index batch processed",
+			"events", batch.Events, "indexed", batch.Indexed, "removed", batch.Removed)
+	}
+	return batch.Events > 0, nil
+}
+
+// PruneOutbox deletes outbox events processed longer than OutboxRetention ago
+func (s *SearchIndexService) PruneOutbox(ctx context.Context) (int, error) {
+	return s.repo.PruneProcessed(ctx, s.now().Add(-OutboxRetention))
+}
+
+// RequestReindex queues a rebuild of the organization's search documents of
+// the requested record types, every indexed one by default
+func (s *SearchIndexService) RequestReindex(ctx context.Context, orgID, userID uuid.UUID, req types.ReindexRequest) (*types.ReindexJob, error) {
+	if err := s.authService.CheckPermission(ctx, "search:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	recordTypes := req.RecordTypes
+	if len(recordTypes) == 0 {
+		recordTypes = types.IndexedRecordTypes
+	}
+	seen := make(map[types.RecordType]bool)
+	var unique []types.RecordType
+	for _, recordType := range recordTypes {
+		if !recordType.IsIndexed() {
+			return nil, fmt.Errorf("record type %s is not indexed", recordType)
+		}
+		if !seen[recordType] {
+			seen[recordType] = true
+			unique = append(unique, recordType)
+		}
+	}
+
+	job := types.ReindexJob{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		RecordTypes:    unique,
+		Status:         types.ReindexStatusQueued,
+		RequestedBy:    &userID,
+		CreatedAt:      s.now(),
+	}
+	job.UpdatedAt = job.CreatedAt
+	if err := s.repo.CreateReindexJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("search reindex requested", "organization_id", orgID, "job_id", job.ID, "record_types", unique)
+	return &job, nil
+}
+
+// ListReindexJobs returns the organization's most recent reindexes
+func (s *SearchIndexService) ListReindexJobs(ctx context.Context, orgID uuid.UUID) ([]types.ReindexJob, error) {
+	if err := s.authService.CheckPermission(ctx, "search:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListReindexJobs(ctx, orgID, reindexHistoryLimit)
+}
+
+// ProcessNextReindex runs the oldest queued reindex. It reports whether
+// there was one.
+func (s *SearchIndexService) ProcessNextReindex(ctx context.Context) (bool, error) {
+	job, err := s.repo.ClaimReindexJob(ctx, s.now().Add(-ReindexStaleAfter))
+	if err != nil || job == nil {
+		return false, err
+	}
+	logger := s.logger.With("job_id", job.ID, "organization_id", job.OrganizationID)
+
+	result, err := s.repo.Reindex(ctx, job.OrganizationID, job.RecordTypes, *job.StartedAt)
+	if err != nil && ctx.Err() != nil {
+		// Leave the job running; it is started over once it goes stale
+		return true, ctx.Err()
+	}
+
+	finished := s.now()
+	job.FinishedAt = &finished
+	if err != nil {
+		logger.Error("search reindex failed", "error", err)
+		message := err.Error()
+		job.Status = types.ReindexStatusFailed
+		job.Error = &message
+	} else {
+		logger.Info("search reindex completed", "indexed", result.Indexed, "removed", result.Removed)
+		job.Status = types.ReindexStatusCompleted
+		job.IndexedCount = result.Indexed
+		job.RemovedCount = result.Removed
+	}
+	return true, s.repo.UpdateReindexJob(ctx, *job)
+}
+
+// GetIndexHealth reports how far behind the organization's search index is
+func (s *SearchIndexService) GetIndexHealth(ctx context.Context, orgID uuid.UUID) (*types.IndexHealth, error) {
+	if err := s.authService.CheckPermission(ctx, "search:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	stats, err := s.repo.OutboxStats(ctx, orgID, MaxIndexAttempts)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountDocuments(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := s.repo.ListReindexJobs(ctx, orgID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	health := BuildIndexHealth(orgID, stats, counts, s.now())
+	if len(jobs) > 0 {
+		health.LatestReindex = &jobs[0]
+	}
+	return health, nil
+}
+
+// BuildIndexHealth derives the index health from the outbox and document counts
+func BuildIndexHealth(orgID uuid.UUID, stats types.OutboxStats, counts []types.DocumentCount, now time.Time) *types.IndexHealth {
+	health := &types.IndexHealth{
+		OrganizationID:  orgID,
+		PendingEvents:   stats.Pending,
+		FailedEvents:    stats.Failed,
+		OldestPendingAt: stats.OldestPendingAt,
+		LastProcessedAt: stats.LastProcessedAt,
+		Documents:       []types.DocumentCount{},
+		CheckedAt:       now,
+	}
+
+	var lag time.Duration
+	if stats.OldestPendingAt != nil && now.After(*stats.OldestPendingAt) {
+		lag = now.Sub(*stats.OldestPendingAt)
+	}
+	health.LagSeconds = lag.Seconds()
+	health.Healthy = stats.Failed == 0 && lag <= MaxHealthyLag
+
+	for _, count := range counts {
+		count.Drift = count.Records - count.Documents
+		health.Documents = append(health.Documents, count)
+	}
+	return health
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/search/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSearchIndexRepo struct {
+	changes []types.IndexChange
+	batch   types.IndexBatch
+	claimed *types.ReindexJob
+	reindex types.ReindexResult
+	err     error
+
+	created []types.ReindexJob
+	updated []types.ReindexJob
+}
+
+func (f *fakeSearchIndexRepo) Enqueue(ctx context.Context, change types.IndexChange) error {
+	f.changes = append(f.changes, change)
+	return nil
+}
+
+func (f *fakeSearchIndexRepo) IndexPending(ctx context.Context, limit, maxAttempts int, retryDelay time.Duration) (types.IndexBatch, error) {
+	batch := f.batch
+	f.batch = types.IndexBatch{}
+	return batch, nil
+}
+
+func (f *fakeSearchIndexRepo) PruneProcessed(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeSearchIndexRepo) OutboxStats(ctx context.Context, orgID uuid.UUID, maxAttempts int) (types.OutboxStats, error) {
+	return types.OutboxStats{}, nil
+}
+
+func (f *fakeSearchIndexRepo) CountDocuments(ctx context.Context, orgID uuid.UUID) ([]types.DocumentCount, error) {
+	return nil, nil
+}
+
+func (f *fakeSearchIndexRepo) CreateReindexJob(ctx context.Context, job types.ReindexJob) error {
+	f.created = append(f.created, job)
+	return nil
+}
+
+func (f *fakeSearchIndexRepo) ListReindexJobs(ctx context.Context, orgID uuid.UUID, limit int) ([]types.ReindexJob, error) {
+	return f.created, nil
+}
+
+func (f *fakeSearchIndexRepo) ClaimReindexJob(ctx context.Context, staleBefore time.Time) (*types.ReindexJob, error) {
+	job := f.claimed
+	f.claimed = nil
+	return job, nil
+}
+
+func (f *fakeSearchIndexRepo) UpdateReindexJob(ctx context.Context, job types.ReindexJob) error {
+	f.updated = append(f.updated, job)
+	return nil
+}
+
+func (f *fakeSearchIndexRepo) Reindex(ctx context.Context, orgID uuid.UUID, recordTypes []types.RecordType, startedAt time.Time) (types.ReindexResult, error) {
+	return f.reindex, f.err
+}
+
+func TestChangeFromEventReadsRecordsAndBulkPayloads(t *testing.T) {
+	orgID, leadID, otherID := uuid.New(), uuid.New(), uuid.New()
+
+	type contact struct {
+		ID             uuid.UUID `json:"id"`
+		OrganizationID uuid.UUID `json:"organization_id"`
+		Name           string    `json:"name"`
+	}
+	change, err := ChangeFromEvent("contact.updated", &contact{ID: leadID, OrganizationID: orgID, Name: "Acme"})
+	require.NoError(t, err)
+	assert.Equal(t, types.IndexChange{
+		OrganizationID: orgID,
+		RecordType:     types.RecordTypeContact,
+		RecordIDs:      []uuid.UUID{leadID},
+		EventType:      "contact.updated",
+	}, change)
+
+	change, err = ChangeFromEvent("crm.lead.bulk_deleted", map[string]interface{}{
+		"organization_id": orgID,
+		"lead_ids":        []uuid.UUID{leadID, otherID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, types.RecordTypeLead, change.RecordType)
+	assert.Equal(t, []uuid.UUID{leadID, otherID}, change.RecordIDs)
+
+	_, err = ChangeFromEvent("order.deleted", map[string]interface{}{"id": leadID})
+	assert.ErrorContains(t, err, "no organization")
+	_, err = ChangeFromEvent("invoice.paid", map[string]interface{}{"organization_id": orgID})
+	assert.ErrorContains(t, err, "no record")
+	_, err = ChangeFromEvent("quote.sent", map[string]interface{}{"id": leadID, "organization_id": orgID})
+	assert.ErrorContains(t, err, "not indexed")
+}
+
+func TestProcessNextBatchKeepsDrainingPastFailures(t *testing.T) {
+	repo := &fakeSearchIndexRepo{batch: types.IndexBatch{
+		Events: 3,
+		Failed: map[types.RecordType]error{types.RecordTypeInvoice: errors.New("boom")},
+	}}
+	svc := NewSearchIndexService(repo, &fakeAuth{}, nil)
+
+	processed, err := svc.ProcessNextBatch(context.Background())
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	processed, err = svc.ProcessNextBatch(context.Background())
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestRequestReindexDefaultsToIndexedTypes(t *testing.T) {
+	repo := &fakeSearchIndexRepo{}
+	svc := NewSearchIndexService(repo, &fakeAuth{}, nil)
+	orgID, userID := uuid.New(), uuid.New()
+
+	job, err := svc.RequestReindex(context.Background(), orgID, userID, types.ReindexRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, types.IndexedRecordTypes, job.RecordTypes)
+	assert.Equal(t, types.ReindexStatusQueued, job.Status)
+	assert.Equal(t, &userID, job.RequestedBy)
+
+	job, err = svc.RequestReindex(context.Background(), orgID, userID, types.ReindexRequest{
+		RecordTypes: []types.RecordType{types.RecordTypeLead, types.RecordTypeLead},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []types.RecordType{types.RecordTypeLead}, job.RecordTypes)
+
+	_, err = svc.RequestReindex(context.Background(), orgID, userID, types.ReindexRequest{
+		RecordTypes: []types.RecordType{types.RecordTypePurchaseOrder},
+	})
+	assert.ErrorContains(t, err, "not indexed")
+
+	denied := NewSearchIndexService(repo, &fakeAuth{permErr: errors.New("no")}, nil)
+	_, err = denied.RequestReindex(context.Background(), orgID, userID, types.ReindexRequest{})
+	assert.ErrorContains(t, err, "permission denied")
+	assert.Len(t, repo.created, 2)
+}
+
+func TestProcessNextReindexRecordsOutcome(t *testing.T) {
+	now := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	started := now.Add(-time.Minute)
+	repo := &fakeSearchIndexRepo{
+		claimed: &types.ReindexJob{ID: uuid.New(), RecordTypes: types.IndexedRecordTypes, StartedAt: &started},
+		reindex: types.ReindexResult{Indexed: 120, Removed: 4},
+	}
+	svc := NewSearchIndexService(repo, &fakeAuth{}, nil)
+	svc.now = func() time.Time { return now }
+
+	processed, err := svc.ProcessNextReindex(context.Background())
+	require.NoError(t, err)
+	assert.True(t, processed)
+	require.Len(t, repo.updated, 1)
+	assert.Equal(t, types.ReindexStatusCompleted, repo.updated[0].Status)
+	assert.Equal(t, 120, repo.updated[0].IndexedCount)
+	assert.Equal(t, 4, repo.updated[0].RemovedCount)
+	assert.Equal(t, &now, repo.updated[0].FinishedAt)
+
+	repo.claimed = &types.ReindexJob{ID: uuid.New(), StartedAt: &started}
+	repo.err = errors.New("boom")
+	_, err = svc.ProcessNextReindex(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, types.ReindexStatusFailed, repo.updated[1].Status)
+	assert.Equal(t, "boom", *repo.updated[1].Error)
+
+	processed, err = svc.ProcessNextReindex(context.Background())
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestBuildIndexHealthReportsLagAndDrift(t *testing.T) {
+	now := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	oldest := now.Add(-90 * time.Second)
+	counts := []types.DocumentCount{
+		{RecordType: types.RecordTypeContact, Documents: 98, Records: 100},
+		{RecordType: types.RecordTypeLead, Documents: 12, Records: 10},
+	}
+
+	health := BuildIndexHealth(uuid.New(), types.OutboxStats{Pending: 7, OldestPendingAt: &oldest}, counts, now)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, 7, health.PendingEvents)
+	assert.Equal(t, 90.0, health.LagSeconds)
+	assert.Equal(t, 2, health.Documents[0].Drift)
+	assert.Equal(t, -2, health.Documents[1].Drift)
+
+	stale := now.Add(-MaxHealthyLag - time.Second)
+	assert.False(t, BuildIndexHealth(uuid.New(), types.OutboxStats{Pending: 1, OldestPendingAt: &stale}, nil, now).Healthy)
+	assert.False(t, BuildIndexHealth(uuid.New(), types.OutboxStats{Failed: 1}, nil, now).Healthy)
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordType identifies the kind of record returned by quick search
+type RecordType string
+
+const (
+	RecordTypeContact       RecordType = "contact"
+	RecordTypeLead          RecordType = "lead"
+	RecordTypeProduct       RecordType = "product"
+	RecordTypeSalesOrder    RecordType = "sales_order"
+	RecordTypeInvoice       RecordType = "invoice"
+	RecordTypePurchaseOrder RecordType = "purchase_order"
+	// RecordTypeCustomRecord is a record of a searchable custom entity. Its
+	// subtitle is the entity label and its reference the entity name.
+	RecordTypeCustomRecord RecordType = "custom_record"
+)
+
+// ErrInvalidQuickSearch is returned when a quick search query or its record
+// types are rejected
+var ErrInvalidQuickSearch = errors.New("invalid quick search")
+
+// AllRecordTypes lists every record type searched when no type filter is given
+var AllRecordTypes = []RecordType{
+	RecordTypeContact,
+	RecordTypeLead,
+	RecordTypeProduct,
+	RecordTypeSalesOrder,
+	RecordTypeInvoice,
+	RecordTypePurchaseOrder,
+	RecordTypeCustomRecord,
+}
+
+// IsValid reports whether the record type is supported by quick search
+func (t RecordType) IsValid() bool {
+	for _, rt := range AllRecordTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadPermission returns the permission needed to read records of the type
+func (t RecordType) ReadPermission() string {
+	switch t {
+	case RecordTypeContact:
+		return "crm:contacts:read"
+	case RecordTypeLead:
+		return "crm:leads:read"
+	case RecordTypeProduct:
+		return "products:read"
+	case RecordTypeSalesOrder:
+		return "sales:sales_orders:read"
+	case RecordTypeInvoice:
+		return "accounting:invoices:read"
+	case RecordTypePurchaseOrder:
+		return "purchase:purchase_orders:read"
+	case RecordTypeCustomRecord:
+		return "custom_entity_records:read"
+	}
+	return ""
+}
+
+// QuickSearchResult is a single "jump to record" hit
+type QuickSearchResult struct {
+	Type      RecordType `json:"type"`
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	Subtitle  *string    `json:"subtitle,omitempty"`
+	Reference *string    `json:"reference,omitempty"`
+	Score     float64    `json:"score"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// QuickSearchFilter represents the criteria for a quick search
+type QuickSearchFilter struct {
+	OrganizationID uuid.UUID
+	Query          string
+	Types          []RecordType
+	PerTypeLimit   int
+}
+
+// QuickSearchResponse groups quick search hits for the command palette
+type QuickSearchResponse struct {
+	Query   string              `json:"query"`
+	Results []QuickSearchResult `json:"results"`
+	Counts  map[RecordType]int  `json:"counts"`
+	TookMs  int64               `json:"took_ms"`
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IndexedRecordTypes lists the record types quick search reads from search
+// documents. Their documents are refreshed from entity change events; the
+// other record types are still searched in their own tables.
+var IndexedRecordTypes = []RecordType{
+	RecordTypeContact,
+	RecordTypeLead,
+	RecordTypeProduct,
+	RecordTypeSalesOrder,
+	RecordTypeInvoice,
+}
+
+// IsIndexed reports whether the record type is served from search documents
+func (t RecordType) IsIndexed() bool {
+	for _, rt := range IndexedRecordTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexChange is a change to records of one type, as read from an entity
+// change event
+type IndexChange struct {
+	OrganizationID uuid.UUID
+	RecordType     RecordType
+	RecordIDs      []uuid.UUID
+	EventType      string
+}
+
+// IndexBatch is the outcome of processing a batch of the index outbox
+type IndexBatch struct {
+	// Events is how many outbox events were claimed
+	Events int
+	// Indexed is how many documents were written
+	Indexed int
+	// Removed is how many documents of deleted records were dropped
+	Removed int
+	// Failed holds the error of each record type that could not be refreshed;
+	// its events are retried later
+	Failed map[RecordType]error
+}
+
+// ReindexStatus represents the state of a reindex job
+type ReindexStatus string
+
+const (
+	ReindexStatusQueued    ReindexStatus = "queued"
+	ReindexStatusRunning   ReindexStatus = "running"
+	ReindexStatusCompleted ReindexStatus = "completed"
+	ReindexStatusFailed    ReindexStatus = "failed"
+)
+
+// ReindexJob rebuilds the search documents of an organization
+type ReindexJob struct {
+	ID             uuid.UUID     `json:"id" db:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id" db:"organization_id"`
+	RecordTypes    []RecordType  `json:"record_types" db:"record_types"`
+	Status         ReindexStatus `json:"status" db:"status"`
+	IndexedCount   int           `json:"indexed_count" db:"indexed_count"`
+	RemovedCount   int           `json:"removed_count" db:"removed_count"`
+	Error          *string       `json:"error,omitempty" db:"error"`
+	RequestedBy    *uuid.UUID    `json:"requested_by,omitempty" db:"requested_by"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+	StartedAt      *time.Time    `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt     *time.Time    `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ReindexRequest asks for the documents of some record types, or of every
+// indexed one when empty, to be rebuilt
+type ReindexRequest struct {
+	RecordTypes []RecordType `json:"record_types,omitempty"`
+}
+
+// ReindexResult is what a reindex changed
+type ReindexResult struct {
+	Indexed int
+	Removed int
+}
+
+// OutboxStats describes the index outbox of an organization
+type OutboxStats struct {
+	Pending         int
+	Failed          int
+	OldestPendingAt *time.Time
+	LastProcessedAt *time.Time
+}
+
+// DocumentCount compares the documents of a record type with its records
+type DocumentCount struct {
+	RecordType RecordType `json:"record_type"`
+	Documents  int        `json:"documents"`
+	Records    int        `json:"records"`
+	// Drift is how many records the documents are missing, negative when
+	// documents of deleted records are still to be removed
+	Drift int `json:"drift"`
+}
+
+// IndexHealth reports how far behind the search index of an organization is
+type IndexHealth struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	// Healthy is false when events failed for good or the lag is over the limit
+	Healthy bool `json:"healthy"`
+	// PendingEvents is how many change events are waiting to be indexed
+	PendingEvents int `json:"pending_events"`
+	// FailedEvents is how many change events ran out of attempts; a reindex
+	// of their record types repairs them
+	FailedEvents    int             `json:"failed_events"`
+	LagSeconds      float64         `json:"lag_seconds"`
+	OldestPendingAt *time.Time      `json:"oldest_pending_at,omitempty"`
+	LastProcessedAt *time.Time      `json:"last_processed_at,omitempty"`
+	Documents       []DocumentCount `json:"documents"`
+	LatestReindex   *ReindexJob     `json:"latest_reindex,omitempty"`
+	CheckedAt       time.Time       `json:"checked_at"`
+}
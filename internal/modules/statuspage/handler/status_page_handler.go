@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// StatusPageHandler handles the public status page and its administration
+type StatusPageHandler struct {
+	service *service.StatusPageService
+}
+
+func NewStatusPageHandler(service *service.StatusPageService) *StatusPageHandler {
+	return &StatusPageHandler{service: service}
+}
+
+func (h *StatusPageHandler) RegisterRoutes(router *httprouter.Router) {
+	// Unauthenticated; see the public route prefix in the auth middleware
+	router.GET("/public/v1/status/:slug", h.PublicStatus)
+
+	router.GET("/api/v1/status-page/settings", h.GetSettings)
+	router.PUT("/api/v1/status-page/settings", h.UpdateSettings)
+	router.GET("/api/v1/status-page/incidents", h.ListIncidents)
+	router.POST("/api/v1/status-page/incidents", h.CreateIncident)
+	router.PUT("/api/v1/status-page/incidents/:id", h.UpdateIncident)
+	router.DELETE("/api/v1/status-page/incidents/:id", h.DeleteIncident)
+}
+
+// PublicStatus handles GET /public/v1/status/:slug. Responses carry an ETag
+// and a max-age matching the server-side cache so CDNs and browsers can
+// serve them without reaching the API.
+func (h *StatusPageHandler) PublicStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status, expiresAt, err := h.service.PublicStatus(r.Context(), ps.ByName("slug"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "Status page not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Status page unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, "Status page unavailable", http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// GetSettings handles GET /api/v1/status-page/settings
+func (h *StatusPageHandler) GetSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/v1/status-page/settings
+func (h *StatusPageHandler) UpdateSettings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), authCtx.OrganizationID, req)
+	if err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// ListIncidents handles GET /api/v1/status-page/incidents
+func (h *StatusPageHandler) ListIncidents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	incidents, err := h.service.ListIncidents(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+// CreateIncident handles POST /api/v1/status-page/incidents
+func (h *StatusPageHandler) CreateIncident(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.IncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	incident, err := h.service.CreateIncident(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, incident)
+}
+
+// UpdateIncident handles PUT /api/v1/status-page/incidents/:id
+func (h *StatusPageHandler) UpdateIncident(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid incident ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.IncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	incident, err := h.service.UpdateIncident(r.Context(), authCtx.OrganizationID, id, req)
+	if err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, incident)
+}
+
+// DeleteIncident handles DELETE /api/v1/status-page/incidents/:id
+func (h *StatusPageHandler) DeleteIncident(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid incident ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteIncident(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeStatusPageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeStatusPageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package statuspage
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// StatusPageModule represents the public status page module
+type StatusPageModule struct {
+	statusPageHandler *handler.StatusPageHandler
+	logger            *slog.Logger
+}
+
+// NewStatusPageModule creates a new status page module
+func NewStatusPageModule() *StatusPageModule {
+	return &StatusPageModule{}
+}
+
+// Name returns the module name
+func (m *StatusPageModule) Name() string {
+	return "statuspage"
+}
+
+// Init initializes the status page module
+func (m *StatusPageModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "statuspage")
+	m.logger.Info("Initializing status page module")
+
+	// Create repositories
+	statusPageRepo := repository.NewStatusPageRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	statusPageService := service.NewStatusPageService(statusPageRepo, authAdapter, m.logger)
+
+	// Create handlers
+	m.statusPageHandler = handler.NewStatusPageHandler(statusPageService)
+
+	m.logger.Info("Status page module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers status page module routes
+func (m *StatusPageModule) RegisterRoutes(router interface{}) {
+	if m.statusPageHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.statusPageHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the status page module
+func (m *StatusPageModule) RegisterEventHandlers(bus interface{}) {
+	// The status page is computed from delivery tables on read; nothing to consume
+}
+
+// Health checks the health of the status page module
+func (m *StatusPageModule) Health() error {
+	return nil
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/types"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when an organization or incident does not exist
+var ErrNotFound = errors.New("not found")
+
+// StatusPageRepo defines the interface for status page repository operations
+type StatusPageRepo interface {
+	FindOrganizationBySlug(ctx context.Context, slug string) (*types.Organization, error)
+	GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error)
+	SaveSettings(ctx context.Context, orgID uuid.UUID, settings types.Settings) error
+	CountPlannedDeliveries(ctx context.Context, orgID uuid.UUID, from, to time.Time) (int, error)
+	RegionDelays(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.RegionDelay, error)
+	ListIncidents(ctx context.Context, filter types.IncidentFilter) ([]types.Incident, error)
+	FindIncident(ctx context.Context, orgID, id uuid.UUID) (*types.Incident, error)
+	SaveIncident(ctx context.Context, incident types.Incident) (*types.Incident, error)
+	DeleteIncident(ctx context.Context, orgID, id uuid.UUID) error
+}
+
+// StatusPageRepository reads aggregate delivery data and stores status page
+// settings and incidents
+type StatusPageRepository struct {
+	db *sql.DB
+}
+
+// Ensure StatusPageRepository implements StatusPageRepo interface
+var _ StatusPageRepo = &StatusPageRepository{}
+
+func NewStatusPageRepository(db *sql.DB) *StatusPageRepository {
+	return &StatusPageRepository{db: db}
+}
+
+func (r *StatusPageRepository) FindOrganizationBySlug(ctx context.Context, slug string) (*types.Organization, error) {
+	query := `
+		SELECT id, name, COALESCE(timezone, 'UTC'), COALESCE(settings->'status_page', '{}'::jsonb)
+		FROM organizations
+		WHERE slug = $1 AND deleted_at IS NULL
+	`
+
+	var org types.Organization
+	var settingsJSON []byte
+	err := r.db.QueryRowContext(ctx, query, slug).Scan(&org.ID, &org.Name, &org.Timezone, &settingsJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("organization %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+	if err := json.Unmarshal(settingsJSON, &org.Settings); err != nil {
+		return nil, fmt.Errorf("invalid status page settings: %w", err)
+	}
+	return &org, nil
+}
+
+func (r *StatusPageRepository) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	query := `SELECT COALESCE(settings->'status_page', '{}'::jsonb) FROM organizations WHERE id = $1 AND deleted_at IS NULL`
+
+	var settingsJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(&settingsJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("organization %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get status page settings: %w", err)
+	}
+
+	var settings types.Settings
+	if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+		return nil, fmt.Errorf("invalid status page settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveSettings replaces the "status_page" key of the organization's settings,
+// leaving other settings untouched
+func (r *StatusPageRepository) SaveSettings(ctx context.Context, orgID uuid.UUID, settings types.Settings) error {
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status page settings: %w", err)
+	}
+
+	query := `
+		UPDATE organizations
+		SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{status_page}', $2::jsonb), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, orgID, string(settingsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to save status page settings: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save status page settings: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("organization %w", ErrNotFound)
+	}
+	return nil
+}
+
+// CountPlannedDeliveries counts shipments expected to arrive in [from, to)
+func (r *StatusPageRepository) CountPlannedDeliveries(ctx context.Context, orgID uuid.UUID, from, to time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM delivery_shipments
+		WHERE organization_id = $1
+			AND deleted_at IS NULL
+			AND status <> 'cancelled'
+			AND estimated_arrival_at >= $2 AND estimated_arrival_at < $3
+	`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, orgID, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count planned deliveries: %w", err)
+	}
+	return count, nil
+}
+
+// RegionDelays summarizes stops planned to arrive in [from, to] by the region
+// of their address. A stop that has not arrived yet is as late as to.
+// Early arrivals count as on time.
+func (r *StatusPageRepository) RegionDelays(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.RegionDelay, error) {
+	query := `
+		SELECT region, COUNT(*), COUNT(*) FILTER (WHERE delay_minutes > 0), COALESCE(AVG(delay_minutes), 0)
+		FROM (
+			SELECT
+				COALESCE(NULLIF(s.address->>'region', ''), NULLIF(s.address->>'state', ''), 'Unspecified') AS region,
+				GREATEST(EXTRACT(EPOCH FROM (COALESCE(s.actual_arrival_at, $3) - s.planned_arrival_at)) / 60, 0) AS delay_minutes
+			FROM delivery_route_stops s
+			JOIN delivery_routes r ON r.id = s.route_id
+			WHERE s.organization_id = $1
+				AND s.planned_arrival_at >= $2 AND s.planned_arrival_at <= $3
+				AND s.status <> 'skipped'
+				AND r.deleted_at IS NULL
+				AND r.status <> 'cancelled'
+		) stops
+		GROUP BY region
+		ORDER BY region
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute region delays: %w", err)
+	}
+	defer rows.Close()
+
+	var delays []types.RegionDelay
+	for rows.Next() {
+		var d types.RegionDelay
+		if err := rows.Scan(&d.Region, &d.Stops, &d.DelayedStops, &d.AverageDelayMinutes); err != nil {
+			return nil, fmt.Errorf("failed to scan region delay: %w", err)
+		}
+		delays = append(delays, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compute region delays: %w", err)
+	}
+	return delays, nil
+}
+
+const incidentColumns = `id, organization_id, title, message, severity, status, region, started_at, resolved_at, created_by, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIncident(row rowScanner) (*types.Incident, error) {
+	var i types.Incident
+	err := row.Scan(
+		&i.ID, &i.OrganizationID, &i.Title, &i.Message, &i.Severity, &i.Status, &i.Region,
+		&i.StartedAt, &i.ResolvedAt, &i.CreatedBy, &i.CreatedAt, &i.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// ListIncidents returns incidents newest first
+func (r *StatusPageRepository) ListIncidents(ctx context.Context, filter types.IncidentFilter) ([]types.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM status_page_incidents WHERE organization_id = $1`
+	args := []interface{}{filter.OrganizationID}
+	if filter.ResolvedSince != nil {
+		args = append(args, *filter.ResolvedSince)
+		query += fmt.Sprintf(" AND (resolved_at IS NULL OR resolved_at >= $%d)", len(args))
+	}
+	query += " ORDER BY started_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []types.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, *i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+func (r *StatusPageRepository) FindIncident(ctx context.Context, orgID, id uuid.UUID) (*types.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM status_page_incidents WHERE organization_id = $1 AND id = $2`
+
+	incident, err := scanIncident(r.db.QueryRowContext(ctx, query, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("incident %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find incident: %w", err)
+	}
+	return incident, nil
+}
+
+func (r *StatusPageRepository) SaveIncident(ctx context.Context, incident types.Incident) (*types.Incident, error) {
+	query := `
+		INSERT INTO status_page_incidents (id, organization_id, title, message, severity, status, region, started_at, resolved_at, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			title = EXCLUDED.title,
+			message = EXCLUDED.message,
+			severity = EXCLUDED.severity,
+			status = EXCLUDED.status,
+			region = EXCLUDED.region,
+			started_at = EXCLUDED.started_at,
+			resolved_at = EXCLUDED.resolved_at,
+			updated_at = NOW()
+		WHERE status_page_incidents.organization_id = EXCLUDED.organization_id
+		RETURNING ` + incidentColumns
+
+	saved, err := scanIncident(r.db.QueryRowContext(ctx, query,
+		incident.ID, incident.OrganizationID, incident.Title, incident.Message, incident.Severity,
+		incident.Status, incident.Region, incident.StartedAt, incident.ResolvedAt, incident.CreatedBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save incident: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *StatusPageRepository) DeleteIncident(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM status_page_incidents WHERE organization_id = $1 AND id = $2`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete incident: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete incident: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("incident %w", ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultCacheSeconds is how long a public status is cached when the organization does not choose
+	DefaultCacheSeconds = 60
+	// MinCacheSeconds keeps the unauthenticated endpoint from reaching the database on every request
+	MinCacheSeconds = 15
+	// MaxCacheSeconds bounds how stale a published status may be
+	MaxCacheSeconds = 3600
+	// DelayWindow is how far back stops count towards the average delay of a region
+	DelayWindow = 24 * time.Hour
+	// ResolvedIncidentWindow is how long resolved incidents stay on the public page
+	ResolvedIncidentWindow = 72 * time.Hour
+	// MaxPublicIncidents caps the incidents shown on the public page
+	MaxPublicIncidents = 20
+)
+
+// ErrInvalid wraps validation failures of settings and incidents
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the status page service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+type cachedStatus struct {
+	orgID     uuid.UUID
+	status    *types.Status
+	expiresAt time.Time
+}
+
+// StatusPageService publishes an organization's aggregate operational health
+// and manages the settings and incident notes behind it
+type StatusPageService struct {
+	repo        repository.StatusPageRepo
+	authService AuthService
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedStatus
+}
+
+func NewStatusPageService(repo repository.StatusPageRepo, authService AuthService, logger *slog.Logger) *StatusPageService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &StatusPageService{
+		repo:        repo,
+		authService: authService,
+		logger:      logger,
+		now:         time.Now,
+		cache:       make(map[string]cachedStatus),
+	}
+}
+
+// PublicStatus returns the status page of the organization with the given
+// slug and when it expires. It is served without authentication, so an
+// unknown slug and a disabled page are indistinguishable, and responses are
+// cached for the organization's cache duration.
+func (s *StatusPageService) PublicStatus(ctx context.Context, slug string) (*types.Status, time.Time, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[slug]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.status, entry.expiresAt, nil
+	}
+
+	org, err := s.repo.FindOrganizationBySlug(ctx, slug)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !org.Settings.Enabled {
+		return nil, time.Time{}, fmt.Errorf("status page %w", repository.ErrNotFound)
+	}
+
+	status, err := s.buildStatus(ctx, org, now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	expiresAt := now.Add(time.Duration(cacheSeconds(org.Settings)) * time.Second)
+	s.mu.Lock()
+	s.cache[slug] = cachedStatus{orgID: org.ID, status: status, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return status, expiresAt, nil
+}
+
+func (s *StatusPageService) buildStatus(ctx context.Context, org *types.Organization, now time.Time) (*types.Status, error) {
+	status := &types.Status{
+		Title:       org.Settings.Title,
+		GeneratedAt: now.UTC(),
+		Incidents:   []types.PublicIncident{},
+	}
+	if status.Title == "" {
+		status.Title = org.Name
+	}
+
+	if org.Settings.ShowDeliveryVolume {
+		// Days follow the organization's timezone so "today" matches what customers see locally
+		loc, err := time.LoadLocation(org.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		local := now.In(loc)
+		startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+		today, err := s.repo.CountPlannedDeliveries(ctx, org.ID, startOfDay, startOfDay.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		week, err := s.repo.CountPlannedDeliveries(ctx, org.ID, startOfDay, startOfDay.AddDate(0, 0, 7))
+		if err != nil {
+			return nil, err
+		}
+		status.DeliveryVolume = &types.DeliveryVolume{Today: today, Next7Days: week}
+	}
+
+	if org.Settings.ShowRegionDelays {
+		regions, err := s.repo.RegionDelays(ctx, org.ID, now.Add(-DelayWindow), now)
+		if err != nil {
+			return nil, err
+		}
+		status.Regions = regions
+	}
+
+	resolvedSince := now.Add(-ResolvedIncidentWindow)
+	incidents, err := s.repo.ListIncidents(ctx, types.IncidentFilter{
+		OrganizationID: org.ID,
+		ResolvedSince:  &resolvedSince,
+		Limit:          MaxPublicIncidents,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range incidents {
+		status.Incidents = append(status.Incidents, types.PublicIncident{
+			Title:      i.Title,
+			Message:    i.Message,
+			Severity:   i.Severity,
+			Status:     i.Status,
+			Region:     i.Region,
+			StartedAt:  i.StartedAt,
+			ResolvedAt: i.ResolvedAt,
+			UpdatedAt:  i.UpdatedAt,
+		})
+	}
+	return status, nil
+}
+
+// invalidate drops the cached public status of the organization so settings
+// and incident changes are published immediately
+func (s *StatusPageService) invalidate(orgID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for slug, entry := range s.cache {
+		if entry.orgID == orgID {
+			delete(s.cache, slug)
+		}
+	}
+}
+
+func cacheSeconds(settings types.Settings) int {
+	if settings.CacheSeconds == 0 {
+		return DefaultCacheSeconds
+	}
+	return settings.CacheSeconds
+}
+
+// GetSettings returns the organization's status page settings
+func (s *StatusPageService) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	if err := s.authService.CheckPermission(ctx, "status_page:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.GetSettings(ctx, orgID)
+}
+
+// UpdateSettings replaces the organization's status page settings
+func (s *StatusPageService) UpdateSettings(ctx context.Context, orgID uuid.UUID, settings types.Settings) (*types.Settings, error) {
+	if err := s.authService.CheckPermission(ctx, "status_page:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	settings.Title = strings.TrimSpace(settings.Title)
+	if len(settings.Title) > 255 {
+		return nil, fmt.Errorf("%w: title must be at most 255 characters", ErrInvalid)
+	}
+	if settings.CacheSeconds == 0 {
+		settings.CacheSeconds = DefaultCacheSeconds
+	}
+	if settings.CacheSeconds < MinCacheSeconds || settings.CacheSeconds > MaxCacheSeconds {
+		return nil, fmt.Errorf("%w: cache_seconds must be between %d and %d", ErrInvalid, MinCacheSeconds, MaxCacheSeconds)
+	}
+
+	if err := s.repo.SaveSettings(ctx, orgID, settings); err != nil {
+		return nil, err
+	}
+	s.invalidate(orgID)
+	s.logger.Info("status page settings updated", "organization_id", orgID, "enabled", settings.Enabled)
+	return &settings, nil
+}
+
+// ListIncidents returns the organization's incidents, newest first
+func (s *StatusPageService) ListIncidents(ctx context.Context, orgID uuid.UUID) ([]types.Incident, error) {
+	if err := s.authService.CheckPermission(ctx, "status_page:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListIncidents(ctx, types.IncidentFilter{OrganizationID: orgID})
+}
+
+// CreateIncident posts a new incident note
+func (s *StatusPageService) CreateIncident(ctx context.Context, orgID, userID uuid.UUID, req types.IncidentRequest) (*types.Incident, error) {
+	if err := s.authService.CheckPermission(ctx, "status_page:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	incident := types.Incident{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		CreatedBy:      &userID,
+	}
+	if err := s.applyIncident(&incident, req); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveIncident(ctx, incident)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(orgID)
+	return saved, nil
+}
+
+// UpdateIncident replaces an incident's details. Moving an incident to
+// resolved records when it was resolved; reopening it clears that.
+func (s *StatusPageService) UpdateIncident(ctx context.Context, orgID, id uuid.UUID, req types.IncidentRequest) (*types.Incident, error) {
+	if err := s.authService.CheckPermission(ctx, "status_page:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	incident, err := s.repo.FindIncident(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyIncident(incident, req); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveIncident(ctx, *incident)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(orgID)
+	return saved, nil
+}
+
+// DeleteIncident removes an incident note
+func (s *StatusPageService) DeleteIncident(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "status_page:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	if err := s.repo.DeleteIncident(ctx, orgID, id); err != nil {
+		return err
+	}
+	s.invalidate(orgID)
+	return nil
+}
+
+func (s *StatusPageService) applyIncident(incident *types.Incident, req types.IncidentRequest) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	if len(title) > 255 {
+		return fmt.Errorf("%w: title must be at most 255 characters", ErrInvalid)
+	}
+	if req.Severity == "" {
+		req.Severity = types.IncidentSeverityMinor
+	}
+	if !req.Severity.IsValid() {
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalid, req.Severity)
+	}
+	if req.Status == "" {
+		req.Status = types.IncidentStatusInvestigating
+	}
+	if !req.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalid, req.Status)
+	}
+
+	now := s.now()
+	incident.Title = title
+	incident.Message = strings.TrimSpace(req.Message)
+	incident.Severity = req.Severity
+	incident.Region = req.Region
+	if req.StartedAt != nil {
+		incident.StartedAt = *req.StartedAt
+	} else if incident.StartedAt.IsZero() {
+		incident.StartedAt = now
+	}
+
+	switch {
+	case req.Status == types.IncidentStatusResolved && incident.ResolvedAt == nil:
+		incident.ResolvedAt = &now
+	case req.Status != types.IncidentStatusResolved:
+		incident.ResolvedAt = nil
+	}
+	incident.Status = req.Status
+	return nil
+}
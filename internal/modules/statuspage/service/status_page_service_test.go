@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/statuspage/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStatusPageRepo struct {
+	org         types.Organization
+	incidents   map[uuid.UUID]types.Incident
+	orgLookups  int
+	volumeCalls [][2]time.Time
+	delayCalls  int
+}
+
+func newFakeStatusPageRepo() *fakeStatusPageRepo {
+	return &fakeStatusPageRepo{
+		org: types.Organization{
+			ID:       uuid.New(),
+			Name:     "Acme Logistics",
+			Timezone: "America/New_York",
+			Settings: types.Settings{Enabled: true, ShowDeliveryVolume: true, ShowRegionDelays: true, CacheSeconds: 60},
+		},
+		incidents: make(map[uuid.UUID]types.Incident),
+	}
+}
+
+func (f *fakeStatusPageRepo) FindOrganizationBySlug(ctx context.Context, slug string) (*types.Organization, error) {
+	f.orgLookups++
+	if slug != "acme" {
+		return nil, fmt.Errorf("organization %w", repository.ErrNotFound)
+	}
+	org := f.org
+	return &org, nil
+}
+
+func (f *fakeStatusPageRepo) GetSettings(ctx context.Context, orgID uuid.UUID) (*types.Settings, error) {
+	settings := f.org.Settings
+	return &settings, nil
+}
+
+func (f *fakeStatusPageRepo) SaveSettings(ctx context.Context, orgID uuid.UUID, settings types.Settings) error {
+	f.org.Settings = settings
+	return nil
+}
+
+func (f *fakeStatusPageRepo) CountPlannedDeliveries(ctx context.Context, orgID uuid.UUID, from, to time.Time) (int, error) {
+	f.volumeCalls = append(f.volumeCalls, [2]time.Time{from, to})
+	return len(f.volumeCalls) * 10, nil
+}
+
+func (f *fakeStatusPageRepo) RegionDelays(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]types.RegionDelay, error) {
+	f.delayCalls++
+	return []types.RegionDelay{{Region: "Northeast", Stops: 4, DelayedStops: 1, AverageDelayMinutes: 7.5}}, nil
+}
+
+func (f *fakeStatusPageRepo) ListIncidents(ctx context.Context, filter types.IncidentFilter) ([]types.Incident, error) {
+	var incidents []types.Incident
+	for _, i := range f.incidents {
+		if filter.ResolvedSince != nil && i.ResolvedAt != nil && i.ResolvedAt.Before(*filter.ResolvedSince) {
+			continue
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, nil
+}
+
+func (f *fakeStatusPageRepo) FindIncident(ctx context.Context, orgID, id uuid.UUID) (*types.Incident, error) {
+	i, ok := f.incidents[id]
+	if !ok || i.OrganizationID != orgID {
+		return nil, fmt.Errorf("incident %w", repository.ErrNotFound)
+	}
+	return &i, nil
+}
+
+func (f *fakeStatusPageRepo) SaveIncident(ctx context.Context, incident types.Incident) (*types.Incident, error) {
+	f.incidents[incident.ID] = incident
+	return &incident, nil
+}
+
+func (f *fakeStatusPageRepo) DeleteIncident(ctx context.Context, orgID, id uuid.UUID) error {
+	delete(f.incidents, id)
+	return nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeStatusPageRepo, now time.Time) *StatusPageService {
+	svc := NewStatusPageService(repo, allowAll{}, nil)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestPublicStatus(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	// 02:00 UTC is still the previous day in New York
+	now := time.Date(2025, 3, 10, 2, 0, 0, 0, time.UTC)
+	svc := newTestService(repo, now)
+
+	status, expiresAt, err := svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Logistics", status.Title)
+	assert.Equal(t, now.Add(60*time.Second), expiresAt)
+	require.NotNil(t, status.DeliveryVolume)
+	assert.Equal(t, 10, status.DeliveryVolume.Today)
+	assert.Equal(t, 20, status.DeliveryVolume.Next7Days)
+	require.Len(t, status.Regions, 1)
+	assert.Empty(t, status.Incidents)
+
+	ny, _ := time.LoadLocation("America/New_York")
+	assert.True(t, repo.volumeCalls[0][0].Equal(time.Date(2025, 3, 9, 0, 0, 0, 0, ny)))
+	assert.True(t, repo.volumeCalls[0][1].Equal(time.Date(2025, 3, 10, 0, 0, 0, 0, ny)))
+}
+
+func TestPublicStatusHidesDisabledSections(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	repo.org.Settings.ShowDeliveryVolume = false
+	repo.org.Settings.ShowRegionDelays = false
+	svc := newTestService(repo, time.Now())
+
+	status, _, err := svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Nil(t, status.DeliveryVolume)
+	assert.Nil(t, status.Regions)
+	assert.Empty(t, repo.volumeCalls)
+	assert.Zero(t, repo.delayCalls)
+}
+
+func TestPublicStatusNotFound(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	svc := newTestService(repo, time.Now())
+
+	_, _, err := svc.PublicStatus(context.Background(), "unknown")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	repo.org.Settings.Enabled = false
+	_, _, err = svc.PublicStatus(context.Background(), "acme")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "a disabled page looks like an unknown one")
+}
+
+func TestPublicStatusIsCachedUntilChanged(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	now := time.Now()
+	svc := newTestService(repo, now)
+
+	_, _, err := svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	_, _, err = svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.orgLookups)
+
+	_, err = svc.CreateIncident(context.Background(), repo.org.ID, uuid.New(), types.IncidentRequest{Title: "Snowstorm delays"})
+	require.NoError(t, err)
+
+	status, _, err := svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.orgLookups)
+	require.Len(t, status.Incidents, 1)
+	assert.Equal(t, types.IncidentSeverityMinor, status.Incidents[0].Severity)
+
+	svc.now = func() time.Time { return now.Add(61 * time.Second) }
+	_, _, err = svc.PublicStatus(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.orgLookups)
+}
+
+func TestUpdateIncidentTracksResolution(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	now := time.Now()
+	svc := newTestService(repo, now)
+
+	incident, err := svc.CreateIncident(context.Background(), repo.org.ID, uuid.New(), types.IncidentRequest{Title: "Depot outage"})
+	require.NoError(t, err)
+	assert.Nil(t, incident.ResolvedAt)
+	assert.Equal(t, now, incident.StartedAt)
+
+	resolved, err := svc.UpdateIncident(context.Background(), repo.org.ID, incident.ID, types.IncidentRequest{Title: "Depot outage", Status: types.IncidentStatusResolved})
+	require.NoError(t, err)
+	require.NotNil(t, resolved.ResolvedAt)
+
+	reopened, err := svc.UpdateIncident(context.Background(), repo.org.ID, incident.ID, types.IncidentRequest{Title: "Depot outage", Status: types.IncidentStatusMonitoring})
+	require.NoError(t, err)
+	assert.Nil(t, reopened.ResolvedAt)
+
+	_, err = svc.UpdateIncident(context.Background(), repo.org.ID, incident.ID, types.IncidentRequest{Title: "Depot outage", Severity: "catastrophic"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestUpdateSettingsValidatesCacheDuration(t *testing.T) {
+	repo := newFakeStatusPageRepo()
+	svc := newTestService(repo, time.Now())
+
+	settings, err := svc.UpdateSettings(context.Background(), repo.org.ID, types.Settings{Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCacheSeconds, settings.CacheSeconds)
+
+	_, err = svc.UpdateSettings(context.Background(), repo.org.ID, types.Settings{Enabled: true, CacheSeconds: 1})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
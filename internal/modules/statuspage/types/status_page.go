@@ -0,0 +1,136 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Settings controls an organization's public status page. They are stored
+// under the "status_page" key of the organization's settings.
+type Settings struct {
+	Enabled bool   `json:"enabled"`
+	Title   string `json:"title,omitempty"`
+	// ShowDeliveryVolume publishes planned delivery counts
+	ShowDeliveryVolume bool `json:"show_delivery_volume"`
+	// ShowRegionDelays publishes the average delay of recent stops per region
+	ShowRegionDelays bool `json:"show_region_delays"`
+	// CacheSeconds is how long the public response may be cached, by the
+	// server and by clients
+	CacheSeconds int `json:"cache_seconds"`
+}
+
+// Organization is the subset of an organization the public status page needs
+type Organization struct {
+	ID       uuid.UUID
+	Name     string
+	Timezone string
+	Settings Settings
+}
+
+// IncidentSeverity ranks how much an incident affects customers
+type IncidentSeverity string
+
+const (
+	IncidentSeverityInfo     IncidentSeverity = "info"
+	IncidentSeverityMinor    IncidentSeverity = "minor"
+	IncidentSeverityMajor    IncidentSeverity = "major"
+	IncidentSeverityCritical IncidentSeverity = "critical"
+)
+
+// IsValid reports whether the severity is supported
+func (s IncidentSeverity) IsValid() bool {
+	switch s {
+	case IncidentSeverityInfo, IncidentSeverityMinor, IncidentSeverityMajor, IncidentSeverityCritical:
+		return true
+	}
+	return false
+}
+
+// IncidentStatus is the lifecycle state of an incident
+type IncidentStatus string
+
+const (
+	IncidentStatusInvestigating IncidentStatus = "investigating"
+	IncidentStatusMonitoring    IncidentStatus = "monitoring"
+	IncidentStatusResolved      IncidentStatus = "resolved"
+)
+
+// IsValid reports whether the status is supported
+func (s IncidentStatus) IsValid() bool {
+	switch s {
+	case IncidentStatusInvestigating, IncidentStatusMonitoring, IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+// Incident is an operational note shown on the public status page
+type Incident struct {
+	ID             uuid.UUID        `json:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	Title          string           `json:"title"`
+	Message        string           `json:"message"`
+	Severity       IncidentSeverity `json:"severity"`
+	Status         IncidentStatus   `json:"status"`
+	Region         *string          `json:"region,omitempty"`
+	StartedAt      time.Time        `json:"started_at"`
+	ResolvedAt     *time.Time       `json:"resolved_at,omitempty"`
+	CreatedBy      *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// IncidentRequest creates or updates an incident
+type IncidentRequest struct {
+	Title     string           `json:"title"`
+	Message   string           `json:"message"`
+	Severity  IncidentSeverity `json:"severity"`
+	Status    IncidentStatus   `json:"status"`
+	Region    *string          `json:"region,omitempty"`
+	StartedAt *time.Time       `json:"started_at,omitempty"`
+}
+
+// IncidentFilter selects incidents. When ResolvedSince is set, only open
+// incidents and those resolved after it are returned.
+type IncidentFilter struct {
+	OrganizationID uuid.UUID
+	ResolvedSince  *time.Time
+	Limit          int
+}
+
+// DeliveryVolume counts planned deliveries
+type DeliveryVolume struct {
+	Today     int `json:"today"`
+	Next7Days int `json:"next_7_days"`
+}
+
+// RegionDelay summarizes recent delivery stops of a region
+type RegionDelay struct {
+	Region              string  `json:"region"`
+	Stops               int     `json:"stops"`
+	DelayedStops        int     `json:"delayed_stops"`
+	AverageDelayMinutes float64 `json:"average_delay_minutes"`
+}
+
+// PublicIncident is an incident as shown to customers
+type PublicIncident struct {
+	Title      string           `json:"title"`
+	Message    string           `json:"message"`
+	Severity   IncidentSeverity `json:"severity"`
+	Status     IncidentStatus   `json:"status"`
+	Region     *string          `json:"region,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// Status is the public status page payload. Sections the organization has
+// not enabled are omitted.
+type Status struct {
+	Title          string           `json:"title"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+	DeliveryVolume *DeliveryVolume  `json:"delivery_volume,omitempty"`
+	Regions        []RegionDelay    `json:"regions,omitempty"`
+	Incidents      []PublicIncident `json:"incidents"`
+}
@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SurveysHandler handles survey definitions, invitations, responses with
+// their detractor follow-ups, score trends and the public survey links
+type SurveysHandler struct {
+	service *service.SurveysService
+}
+
+func NewSurveysHandler(service *service.SurveysService) *SurveysHandler {
+	return &SurveysHandler{service: service}
+}
+
+func (h *SurveysHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/surveys", h.ListSurveys)
+	router.POST("/api/v1/surveys", h.CreateSurvey)
+	router.GET("/api/v1/surveys/:id", h.GetSurvey)
+	router.PUT("/api/v1/surveys/:id", h.UpdateSurvey)
+	router.DELETE("/api/v1/surveys/:id", h.DeleteSurvey)
+
+	router.GET("/api/v1/survey-invitations", h.ListInvitations)
+	router.GET("/api/v1/survey-responses", h.ListResponses)
+	router.POST("/api/v1/survey-responses/:id/resolve", h.ResolveFollowUp)
+	router.GET("/api/v1/survey-trend", h.Trend)
+
+	// Public: reached with the links sent to customers
+	router.GET(service.ResponsePath+":token", h.GetPublicSurvey)
+	router.POST(service.ResponsePath+":token", h.Respond)
+}
+
+// ListSurveys handles GET /api/v1/surveys
+func (h *SurveysHandler) ListSurveys(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	surveys, err := h.service.ListSurveys(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, surveys)
+}
+
+// CreateSurvey handles POST /api/v1/surveys
+func (h *SurveysHandler) CreateSurvey(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SurveyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.service.CreateSurvey(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, survey)
+}
+
+// GetSurvey handles GET /api/v1/surveys/:id
+func (h *SurveysHandler) GetSurvey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.service.GetSurvey(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, survey)
+}
+
+// UpdateSurvey handles PUT /api/v1/surveys/:id
+func (h *SurveysHandler) UpdateSurvey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SurveyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	survey, err := h.service.UpdateSurvey(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, survey)
+}
+
+// DeleteSurvey handles DELETE /api/v1/surveys/:id
+func (h *SurveysHandler) DeleteSurvey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSurvey(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListInvitations handles GET /api/v1/survey-invitations with optional
+// survey_id and status filters
+func (h *SurveysHandler) ListInvitations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.InvitationFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.InvitationStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("survey_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+			return
+		}
+		filter.SurveyID = &id
+	}
+
+	invitations, err := h.service.ListInvitations(r.Context(), filter)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// ListResponses handles GET /api/v1/survey-responses with optional
+// survey_id, category and follow_up_status filters
+func (h *SurveysHandler) ListResponses(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.ResponseFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Category:       types.Category(q.Get("category")),
+		FollowUpStatus: types.FollowUpStatus(q.Get("follow_up_status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("survey_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+			return
+		}
+		filter.SurveyID = &id
+	}
+
+	responses, err := h.service.ListResponses(r.Context(), filter)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// ResolveFollowUp handles POST /api/v1/survey-responses/:id/resolve
+func (h *SurveysHandler) ResolveFollowUp(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid response ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.FollowUpRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response, err := h.service.ResolveFollowUp(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Trend handles GET /api/v1/survey-trend with optional kind, interval,
+// segment_by, survey_id, from and to
+func (h *SurveysHandler) Trend(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.TrendFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Kind:           types.Kind(q.Get("kind")),
+		Interval:       types.Interval(q.Get("interval")),
+		SegmentBy:      types.Segment(q.Get("segment_by")),
+	}
+	if v := q.Get("survey_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid survey ID", http.StatusBadRequest)
+			return
+		}
+		filter.SurveyID = &id
+	}
+	for name, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+" date", http.StatusBadRequest)
+				return
+			}
+			*dest = t
+		}
+	}
+
+	trend, err := h.service.Trend(r.Context(), filter)
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, trend)
+}
+
+// GetPublicSurvey handles GET /public/v1/surveys/:token, the question an
+// invitation link asks
+func (h *SurveysHandler) GetPublicSurvey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	survey, err := h.service.GetPublicSurvey(r.Context(), ps.ByName("token"))
+	if err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, survey)
+}
+
+// Respond handles POST /public/v1/surveys/:token, a customer's answer
+func (h *SurveysHandler) Respond(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req types.ResponseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.Respond(r.Context(), ps.ByName("token"), req); err != nil {
+		writeSurveysError(w, err)
+		return
+	}
+
+	// The customer only learns the answer was recorded
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSurveysError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAlreadyResponded), errors.Is(err, repository.ErrFollowUpClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeSurveysDispatch = "surveys.dispatch"
+
+// DispatchJobHandler handles queued passes sending due survey invitations
+type DispatchJobHandler struct {
+	surveysService *service.SurveysService
+	logger         *slog.Logger
+}
+
+func NewDispatchJobHandler(surveysService *service.SurveysService, logger *slog.Logger) *DispatchJobHandler {
+	return &DispatchJobHandler{
+		surveysService: surveysService,
+		logger:         logger,
+	}
+}
+
+// Handle sends the invitations due
+func (h *DispatchJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	result, err := h.surveysService.Dispatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to dispatch survey invitations: %w", err)
+	}
+	if result.Sent+result.Failed+result.Skipped > 0 {
+		h.logger.Info("Survey invitations dispatched",
+			"sent", result.Sent,
+			"failed", result.Failed,
+			"skipped", result.Skipped)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DispatchJobHandler) JobType() string {
+	return JobTypeSurveysDispatch
+}
+
+// Scheduler sends due survey invitations on a fixed interval, so surveys go
+// out once their delay after the event has passed
+type Scheduler struct {
+	handler     *DispatchJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DispatchJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start sends due invitations every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeSurveysDispatch, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeSurveysDispatch, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled survey dispatch failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule survey dispatch", "error", err)
+	}
+}
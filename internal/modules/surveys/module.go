@@ -0,0 +1,189 @@
+package surveys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Events surveys are sent after
+const (
+	eventLeadWon         = "crm.lead.won"
+	eventShipmentUpdated = "delivery_shipment.status_updated"
+	eventTicketClosed    = "portal.ticket_closed"
+)
+
+// SurveysModule represents the customer survey module
+type SurveysModule struct {
+	surveysService *service.SurveysService
+	surveysHandler *handler.SurveysHandler
+	scheduler      *jobs.Scheduler
+	logger         *slog.Logger
+}
+
+// NewSurveysModule creates a new surveys module
+func NewSurveysModule() *SurveysModule {
+	return &SurveysModule{}
+}
+
+// Name returns the module name
+func (m *SurveysModule) Name() string {
+	return "surveys"
+}
+
+// SetMailer emails survey invitations and detractor alerts. It must be
+// called after Init.
+func (m *SurveysModule) SetMailer(mailer service.Mailer) {
+	if m.surveysService != nil {
+		m.surveysService.SetMailer(mailer)
+	}
+}
+
+// SetSMSSender texts survey invitations. It must be called after Init.
+func (m *SurveysModule) SetSMSSender(sms service.SMSSender) {
+	if m.surveysService != nil {
+		m.surveysService.SetSMSSender(sms)
+	}
+}
+
+// SetConsentChecker skips the invitations of customers who opted out of
+// the survey's channel. It must be called after Init.
+func (m *SurveysModule) SetConsentChecker(consents service.ConsentChecker) {
+	if m.surveysService != nil {
+		m.surveysService.SetConsentChecker(consents)
+	}
+}
+
+// SetPublicURL is where the survey links sent to customers are served. It
+// must be called after Init.
+func (m *SurveysModule) SetPublicURL(url string) {
+	if m.surveysService != nil {
+		m.surveysService.SetPublicURL(url)
+	}
+}
+
+// Init initializes the surveys module and starts sending due invitations
+func (m *SurveysModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "surveys")
+	m.logger.Info("Initializing surveys module")
+
+	// Create repositories
+	surveysRepo := repository.NewSurveysRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.surveysService = service.NewSurveysService(surveysRepo, authAdapter, deps.EventBus, m.logger)
+
+	// Create scheduled dispatch
+	dispatchJobHandler := jobs.NewDispatchJobHandler(m.surveysService, m.logger)
+	m.scheduler = jobs.NewScheduler(dispatchJobHandler, service.DispatchInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.surveysHandler = handler.NewSurveysHandler(m.surveysService)
+
+	m.logger.Info("Surveys module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers surveys module routes
+func (m *SurveysModule) RegisterRoutes(router interface{}) {
+	if m.surveysHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.surveysHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers invites customers to surveys when a deal is won, a
+// shipment is delivered and a support ticket is closed
+func (m *SurveysModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.surveysService == nil {
+		return
+	}
+
+	eventBus.Subscribe(eventLeadWon, m.handleTriggerEvent(types.TriggerDealWon, func(p triggerPayload) (uuid.UUID, bool) {
+		return p.LeadID, true
+	}))
+	eventBus.Subscribe(eventShipmentUpdated, m.handleTriggerEvent(types.TriggerDeliveryCompleted, func(p triggerPayload) (uuid.UUID, bool) {
+		return p.ID, p.Status == "delivered"
+	}))
+	eventBus.Subscribe(eventTicketClosed, m.handleTriggerEvent(types.TriggerTicketClosed, func(p triggerPayload) (uuid.UUID, bool) {
+		return p.TicketID, true
+	}))
+
+	m.logger.Info("Surveys module event handlers registered")
+}
+
+// triggerPayload holds the fields of the trigger events surveys read
+type triggerPayload struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	ID             uuid.UUID `json:"id"`
+	LeadID         uuid.UUID `json:"lead_id"`
+	TicketID       uuid.UUID `json:"ticket_id"`
+	Status         string    `json:"status"`
+}
+
+// handleTriggerEvent invites the customer of the event's source record,
+// which source picks from the payload along with whether the event counts.
+// Failures are logged rather than returned so they never fail the change
+// that raised the event.
+func (m *SurveysModule) handleTriggerEvent(trigger types.Trigger, source func(triggerPayload) (uuid.UUID, bool)) events.HandlerFunc {
+	return func(ctx context.Context, event events.Event) error {
+		// Sandbox organizations never survey real customers
+		if event.Sandbox {
+			return nil
+		}
+
+		// The payload might be the struct itself or a map, depending on how it was published
+		var payload triggerPayload
+		bytes, err := json.Marshal(event.Payload)
+		if err == nil {
+			err = json.Unmarshal(bytes, &payload)
+		}
+		if err != nil {
+			m.logger.Error("Failed to decode survey trigger event", "event", event.Type, "error", err)
+			return nil
+		}
+
+		sourceID, ok := source(payload)
+		if !ok || sourceID == uuid.Nil || payload.OrganizationID == uuid.Nil {
+			return nil
+		}
+
+		invitations, err := m.surveysService.HandleEvent(ctx, types.TriggerEvent{
+			OrganizationID: payload.OrganizationID,
+			Trigger:        trigger,
+			SourceID:       sourceID,
+			OccurredAt:     event.Timestamp,
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			m.logger.Info("Survey trigger has no customer", "trigger", trigger, "source_id", sourceID)
+		case err != nil:
+			m.logger.Error("Failed to invite customer to survey", "trigger", trigger, "source_id", sourceID, "error", err)
+		case len(invitations) > 0:
+			m.logger.Info("Customer invited to surveys", "trigger", trigger, "source_id", sourceID, "invitations", len(invitations))
+		}
+		return nil
+	}
+}
+
+// Health checks the health of the surveys module
+func (m *SurveysModule) Health() error {
+	return nil
+}
@@ -0,0 +1,576 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a survey, invitation, response or the
+	// source record of an event does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when the survey already invited the customer
+	// for the source record
+	ErrDuplicate = errors.New("already exists")
+	// ErrAlreadyResponded is returned when the invitation was already answered
+	ErrAlreadyResponded = errors.New("survey already answered")
+	// ErrFollowUpClosed is returned when resolving a response without an
+	// open follow-up
+	ErrFollowUpClosed = errors.New("response has no open follow-up")
+)
+
+// SurveysRepo defines the interface for surveys repository operations
+type SurveysRepo interface {
+	ListSurveys(ctx context.Context, orgID uuid.UUID) ([]types.Survey, error)
+	FindSurvey(ctx context.Context, orgID, id uuid.UUID) (*types.Survey, error)
+	CreateSurvey(ctx context.Context, orgID, userID uuid.UUID, request types.SurveyRequest) (*types.Survey, error)
+	UpdateSurvey(ctx context.Context, orgID, userID, id uuid.UUID, request types.SurveyRequest) (*types.Survey, error)
+	DeleteSurvey(ctx context.Context, orgID, id uuid.UUID) error
+	ActiveSurveys(ctx context.Context, orgID uuid.UUID, trigger types.Trigger) ([]types.Survey, error)
+	FindRecipient(ctx context.Context, orgID uuid.UUID, trigger types.Trigger, sourceID uuid.UUID) (*types.Recipient, error)
+	CreateInvitation(ctx context.Context, invitation types.Invitation) (*types.Invitation, error)
+	DueInvitations(ctx context.Context, now time.Time, maxAttempts, limit int) ([]types.Invitation, error)
+	MarkSent(ctx context.Context, id uuid.UUID, tokenHash string, at time.Time) error
+	MarkUnsent(ctx context.Context, id uuid.UUID, status types.InvitationStatus, reason string) error
+	ListInvitations(ctx context.Context, filter types.InvitationFilter) ([]types.Invitation, error)
+	FindInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error)
+	SaveResponse(ctx context.Context, response types.Response) (*types.Response, error)
+	ListResponses(ctx context.Context, filter types.ResponseFilter) ([]types.Response, error)
+	ResolveFollowUp(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time) (*types.Response, error)
+	Trend(ctx context.Context, filter types.TrendFilter) ([]types.TrendCounts, error)
+}
+
+// SurveysRepository stores surveys, their invitations and responses, and
+// reads who the source records of trigger events belong to
+type SurveysRepository struct {
+	db *sql.DB
+}
+
+// Ensure SurveysRepository implements SurveysRepo interface
+var _ SurveysRepo = &SurveysRepository{}
+
+func NewSurveysRepository(db *sql.DB) *SurveysRepository {
+	return &SurveysRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+const surveyColumns = `
+	id, organization_id, name, kind, trigger, channel, question, message, delay_minutes, expiry_days,
+	detractor_alert_emails, active, created_at, updated_at, created_by
+`
+
+func scanSurvey(row rowScanner) (*types.Survey, error) {
+	var s types.Survey
+	var createdBy uuid.NullUUID
+	err := row.Scan(&s.ID, &s.OrganizationID, &s.Name, &s.Kind, &s.Trigger, &s.Channel, &s.Question, &s.Message,
+		&s.DelayMinutes, &s.ExpiryDays, pq.Array(&s.DetractorAlertEmails), &s.Active, &s.CreatedAt, &s.UpdatedAt, &createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if s.DetractorAlertEmails == nil {
+		s.DetractorAlertEmails = []string{}
+	}
+	s.CreatedBy = nullUUID(createdBy)
+	return &s, nil
+}
+
+func (r *SurveysRepository) listSurveys(ctx context.Context, query string, args ...interface{}) ([]types.Survey, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list surveys: %w", err)
+	}
+	defer rows.Close()
+
+	surveys := []types.Survey{}
+	for rows.Next() {
+		s, err := scanSurvey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey: %w", err)
+		}
+		surveys = append(surveys, *s)
+	}
+	return surveys, rows.Err()
+}
+
+func (r *SurveysRepository) ListSurveys(ctx context.Context, orgID uuid.UUID) ([]types.Survey, error) {
+	return r.listSurveys(ctx, `
+		SELECT `+surveyColumns+` FROM surveys WHERE organization_id = $1 ORDER BY name
+	`, orgID)
+}
+
+// ActiveSurveys returns the organization's active surveys sent after the trigger
+func (r *SurveysRepository) ActiveSurveys(ctx context.Context, orgID uuid.UUID, trigger types.Trigger) ([]types.Survey, error) {
+	return r.listSurveys(ctx, `
+		SELECT `+surveyColumns+` FROM surveys WHERE organization_id = $1 AND trigger = $2 AND active ORDER BY name
+	`, orgID, trigger)
+}
+
+func (r *SurveysRepository) FindSurvey(ctx context.Context, orgID, id uuid.UUID) (*types.Survey, error) {
+	s, err := scanSurvey(r.db.QueryRowContext(ctx, `
+		SELECT `+surveyColumns+` FROM surveys WHERE id = $1 AND organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("survey %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find survey: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SurveysRepository) CreateSurvey(ctx context.Context, orgID, userID uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	active := request.Active == nil || *request.Active
+	s, err := scanSurvey(r.db.QueryRowContext(ctx, `
+		INSERT INTO surveys (
+			organization_id, name, kind, trigger, channel, question, message, delay_minutes, expiry_days,
+			detractor_alert_emails, active, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		RETURNING `+surveyColumns,
+		orgID, request.Name, request.Kind, request.Trigger, request.Channel, request.Question, request.Message,
+		request.DelayMinutes, request.ExpiryDays, pq.Array(request.DetractorAlertEmails), active, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create survey: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SurveysRepository) UpdateSurvey(ctx context.Context, orgID, userID, id uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	active := request.Active == nil || *request.Active
+	s, err := scanSurvey(r.db.QueryRowContext(ctx, `
+		UPDATE surveys SET
+			name = $3, kind = $4, trigger = $5, channel = $6, question = $7, message = $8, delay_minutes = $9,
+			expiry_days = $10, detractor_alert_emails = $11, active = $12, updated_by = $13, updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+surveyColumns,
+		id, orgID, request.Name, request.Kind, request.Trigger, request.Channel, request.Question, request.Message,
+		request.DelayMinutes, request.ExpiryDays, pq.Array(request.DetractorAlertEmails), active, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("survey %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update survey: %w", err)
+	}
+	return s, nil
+}
+
+// DeleteSurvey removes a survey with its invitations and responses
+func (r *SurveysRepository) DeleteSurvey(ctx context.Context, orgID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM surveys WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete survey: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("survey %w", ErrNotFound)
+	}
+	return nil
+}
+
+// recipientQueries read the customer of a trigger's source record: the
+// contact of a won lead, falling back to the lead's own details, the
+// partner of a delivered shipment's picking and the contact of a ticket.
+// Mobile numbers are preferred for SMS.
+var recipientQueries = map[types.Trigger]string{
+	types.TriggerDealWon: `
+		SELECT l.contact_id, COALESCE(NULLIF(c.name, ''), l.contact_name, ''),
+			COALESCE(NULLIF(c.email, ''), l.email, ''),
+			COALESCE(NULLIF(c.mobile, ''), NULLIF(c.phone, ''), NULLIF(l.mobile, ''), l.phone, '')
+		FROM leads l
+		LEFT JOIN contacts c ON c.id = l.contact_id
+		WHERE l.id = $2 AND l.organization_id = $1
+	`,
+	types.TriggerDeliveryCompleted: `
+		SELECT c.id, c.name, COALESCE(c.email, ''), COALESCE(NULLIF(c.mobile, ''), c.phone, '')
+		FROM delivery_shipments s
+		JOIN stock_pickings p ON p.id = s.picking_id
+		JOIN contacts c ON c.id = p.partner_id
+		WHERE s.id = $2 AND s.organization_id = $1
+	`,
+	types.TriggerTicketClosed: `
+		SELECT c.id, c.name, COALESCE(c.email, ''), COALESCE(NULLIF(c.mobile, ''), c.phone, '')
+		FROM support_tickets t
+		JOIN contacts c ON c.id = t.contact_id
+		WHERE t.id = $2 AND t.organization_id = $1
+	`,
+}
+
+// FindRecipient returns the customer the source record of a trigger event
+// belongs to
+func (r *SurveysRepository) FindRecipient(ctx context.Context, orgID uuid.UUID, trigger types.Trigger, sourceID uuid.UUID) (*types.Recipient, error) {
+	query, ok := recipientQueries[trigger]
+	if !ok {
+		return nil, fmt.Errorf("unknown survey trigger %q", trigger)
+	}
+
+	var recipient types.Recipient
+	var contactID uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, query, orgID, sourceID).Scan(&contactID, &recipient.Name, &recipient.Email, &recipient.Phone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s source %w", trigger, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find survey recipient: %w", err)
+	}
+	recipient.ContactID = nullUUID(contactID)
+	return &recipient, nil
+}
+
+const invitationColumns = `
+	id, organization_id, survey_id, trigger, source_id, contact_id, recipient_name, email, phone, channel, status,
+	send_at, expires_at, sent_at, responded_at, attempts, COALESCE(error, ''), created_at
+`
+
+func scanInvitation(row rowScanner) (*types.Invitation, error) {
+	var i types.Invitation
+	var contactID uuid.NullUUID
+	var sentAt, respondedAt sql.NullTime
+	err := row.Scan(&i.ID, &i.OrganizationID, &i.SurveyID, &i.Trigger, &i.SourceID, &contactID, &i.RecipientName,
+		&i.Email, &i.Phone, &i.Channel, &i.Status, &i.SendAt, &i.ExpiresAt, &sentAt, &respondedAt, &i.Attempts,
+		&i.Error, &i.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	i.ContactID = nullUUID(contactID)
+	i.SentAt = nullTime(sentAt)
+	i.RespondedAt = nullTime(respondedAt)
+	return &i, nil
+}
+
+func (r *SurveysRepository) listInvitations(ctx context.Context, query string, args ...interface{}) ([]types.Invitation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list survey invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []types.Invitation{}
+	for rows.Next() {
+		i, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey invitation: %w", err)
+		}
+		invitations = append(invitations, *i)
+	}
+	return invitations, rows.Err()
+}
+
+// CreateInvitation records an invitation to send. A survey invites once per
+// source record, returning ErrDuplicate for the same record again.
+func (r *SurveysRepository) CreateInvitation(ctx context.Context, invitation types.Invitation) (*types.Invitation, error) {
+	i, err := scanInvitation(r.db.QueryRowContext(ctx, `
+		INSERT INTO survey_invitations (
+			organization_id, survey_id, trigger, source_id, contact_id, recipient_name, email, phone, channel,
+			status, send_at, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (survey_id, source_id) DO NOTHING
+		RETURNING `+invitationColumns,
+		invitation.OrganizationID, invitation.SurveyID, invitation.Trigger, invitation.SourceID, invitation.ContactID,
+		invitation.RecipientName, invitation.Email, invitation.Phone, invitation.Channel, invitation.Status,
+		invitation.SendAt, invitation.ExpiresAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("survey invitation %w", ErrDuplicate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create survey invitation: %w", err)
+	}
+	return i, nil
+}
+
+// DueInvitations returns the pending and failed invitations of every
+// organization that are due and not yet expired, oldest first
+func (r *SurveysRepository) DueInvitations(ctx context.Context, now time.Time, maxAttempts, limit int) ([]types.Invitation, error) {
+	return r.listInvitations(ctx, `
+		SELECT `+invitationColumns+` FROM survey_invitations
+		WHERE status IN ('pending', 'failed') AND attempts < $2 AND send_at <= $1 AND expires_at > $1
+		ORDER BY send_at, id
+		LIMIT $3
+	`, now, maxAttempts, limit)
+}
+
+// MarkSent records that an invitation went out with the token hash its
+// link carries
+func (r *SurveysRepository) MarkSent(ctx context.Context, id uuid.UUID, tokenHash string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE survey_invitations SET
+			status = 'sent', token_hash = $2, sent_at = $3, attempts = attempts + 1, error = NULL
+		WHERE id = $1
+	`, id, tokenHash, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark survey invitation sent: %w", err)
+	}
+	return nil
+}
+
+// MarkUnsent records a failed or skipped send and why
+func (r *SurveysRepository) MarkUnsent(ctx context.Context, id uuid.UUID, status types.InvitationStatus, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE survey_invitations SET status = $2, error = $3, attempts = attempts + 1 WHERE id = $1
+	`, id, status, reason)
+	if err != nil {
+		return fmt.Errorf("failed to update survey invitation: %w", err)
+	}
+	return nil
+}
+
+func (r *SurveysRepository) ListInvitations(ctx context.Context, filter types.InvitationFilter) ([]types.Invitation, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.SurveyID != nil {
+		args = append(args, *filter.SurveyID)
+		where = append(where, fmt.Sprintf("survey_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	return r.listInvitations(ctx, `
+		SELECT `+invitationColumns+` FROM survey_invitations
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+}
+
+// FindInvitationByToken returns the sent invitation whose link carries the
+// token with the hash
+func (r *SurveysRepository) FindInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error) {
+	i, err := scanInvitation(r.db.QueryRowContext(ctx, `
+		SELECT `+invitationColumns+` FROM survey_invitations WHERE token_hash = $1
+	`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("survey invitation %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find survey invitation: %w", err)
+	}
+	return i, nil
+}
+
+const responseColumns = `
+	id, organization_id, survey_id, invitation_id, contact_id, score, category, comment, responded_at,
+	follow_up_status, COALESCE(follow_up_note, ''), follow_up_resolved_at, follow_up_resolved_by
+`
+
+func scanResponse(row rowScanner) (*types.Response, error) {
+	var resp types.Response
+	var contactID, resolvedBy uuid.NullUUID
+	var followUp sql.NullString
+	var resolvedAt sql.NullTime
+	err := row.Scan(&resp.ID, &resp.OrganizationID, &resp.SurveyID, &resp.InvitationID, &contactID, &resp.Score,
+		&resp.Category, &resp.Comment, &resp.RespondedAt, &followUp, &resp.FollowUpNote, &resolvedAt, &resolvedBy)
+	if err != nil {
+		return nil, err
+	}
+	resp.ContactID = nullUUID(contactID)
+	if followUp.Valid {
+		status := types.FollowUpStatus(followUp.String)
+		resp.FollowUpStatus = &status
+	}
+	resp.FollowUpResolvedAt = nullTime(resolvedAt)
+	resp.FollowUpResolvedBy = nullUUID(resolvedBy)
+	return &resp, nil
+}
+
+// SaveResponse records the answer to an invitation and marks it responded.
+// An invitation is answered once; a second answer returns ErrAlreadyResponded.
+func (r *SurveysRepository) SaveResponse(ctx context.Context, response types.Response) (*types.Response, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE survey_invitations SET status = 'responded', responded_at = $2
+		WHERE id = $1 AND status <> 'responded'
+	`, response.InvitationID, response.RespondedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update survey invitation: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrAlreadyResponded
+	}
+
+	saved, err := scanResponse(tx.QueryRowContext(ctx, `
+		INSERT INTO survey_responses (
+			organization_id, survey_id, invitation_id, contact_id, score, category, comment, responded_at,
+			follow_up_status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+responseColumns,
+		response.OrganizationID, response.SurveyID, response.InvitationID, response.ContactID, response.Score,
+		response.Category, response.Comment, response.RespondedAt, response.FollowUpStatus))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrAlreadyResponded
+		}
+		return nil, fmt.Errorf("failed to save survey response: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit survey response: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *SurveysRepository) ListResponses(ctx context.Context, filter types.ResponseFilter) ([]types.Response, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.SurveyID != nil {
+		args = append(args, *filter.SurveyID)
+		where = append(where, fmt.Sprintf("survey_id = $%d", len(args)))
+	}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		where = append(where, fmt.Sprintf("category = $%d", len(args)))
+	}
+	if filter.FollowUpStatus != "" {
+		args = append(args, filter.FollowUpStatus)
+		where = append(where, fmt.Sprintf("follow_up_status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+responseColumns+` FROM survey_responses
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY responded_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list survey responses: %w", err)
+	}
+	defer rows.Close()
+
+	responses := []types.Response{}
+	for rows.Next() {
+		resp, err := scanResponse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey response: %w", err)
+		}
+		responses = append(responses, *resp)
+	}
+	return responses, rows.Err()
+}
+
+// ResolveFollowUp closes the open follow-up of a detractor response
+func (r *SurveysRepository) ResolveFollowUp(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time) (*types.Response, error) {
+	resp, err := scanResponse(r.db.QueryRowContext(ctx, `
+		UPDATE survey_responses SET
+			follow_up_status = 'resolved', follow_up_note = NULLIF($3, ''), follow_up_resolved_at = $4,
+			follow_up_resolved_by = $5
+		WHERE id = $1 AND organization_id = $2 AND follow_up_status = 'open'
+		RETURNING `+responseColumns,
+		id, orgID, note, at, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM survey_responses WHERE id = $1 AND organization_id = $2)
+		`, id, orgID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to find survey response: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("survey response %w", ErrNotFound)
+		}
+		return nil, ErrFollowUpClosed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve follow-up: %w", err)
+	}
+	return resp, nil
+}
+
+// segmentColumns are the key and name a trend is split by, with the joins
+// they need
+var segmentColumns = map[types.Segment]struct{ key, name, join string }{
+	types.SegmentNone:    {key: `''`, name: `''`},
+	types.SegmentSurvey:  {key: `s.id::text`, name: `s.name`},
+	types.SegmentTrigger: {key: `i.trigger`, name: `i.trigger`},
+	types.SegmentChannel: {key: `i.channel`, name: `i.channel`},
+	types.SegmentContactSegment: {
+		key:  `COALESCE(cs.id::text, '')`,
+		name: `COALESCE(cs.name, '')`,
+		join: `
+			LEFT JOIN contact_segment_members m ON m.contact_id = r.contact_id AND m.organization_id = r.organization_id
+			LEFT JOIN contact_segments cs ON cs.id = m.segment_id
+		`,
+	},
+}
+
+// Trend counts the responses of a kind per period and segment. Periods
+// start on Monday or the first of the month, in UTC.
+func (r *SurveysRepository) Trend(ctx context.Context, filter types.TrendFilter) ([]types.TrendCounts, error) {
+	segment, ok := segmentColumns[filter.SegmentBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown survey segment %q", filter.SegmentBy)
+	}
+	interval := "week"
+	if filter.Interval == types.IntervalMonth {
+		interval = "month"
+	}
+
+	where := []string{"r.organization_id = $1", "s.kind = $2", "r.responded_at >= $3", "r.responded_at < $4"}
+	args := []interface{}{filter.OrganizationID, filter.Kind, filter.From, filter.To}
+	if filter.SurveyID != nil {
+		args = append(args, *filter.SurveyID)
+		where = append(where, fmt.Sprintf("r.survey_id = $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date_trunc('`+interval+`', r.responded_at AT TIME ZONE 'UTC') AS period,
+			`+segment.key+` AS segment_key, `+segment.name+` AS segment_name,
+			count(*),
+			count(*) FILTER (WHERE r.category = 'promoter'),
+			count(*) FILTER (WHERE r.category = 'passive'),
+			count(*) FILTER (WHERE r.category = 'detractor'),
+			avg(r.score)::float8
+		FROM survey_responses r
+		JOIN surveys s ON s.id = r.survey_id
+		JOIN survey_invitations i ON i.id = r.invitation_id
+		`+segment.join+`
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 3, 2
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read survey trend: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []types.TrendCounts{}
+	for rows.Next() {
+		var c types.TrendCounts
+		if err := rows.Scan(&c.PeriodStart, &c.SegmentKey, &c.SegmentName, &c.Responses, &c.Promoters, &c.Passives,
+			&c.Detractors, &c.AverageScore); err != nil {
+			return nil, fmt.Errorf("failed to scan survey trend: %w", err)
+		}
+		c.PeriodStart = time.Date(c.PeriodStart.Year(), c.PeriodStart.Month(), c.PeriodStart.Day(), 0, 0, 0, 0, time.UTC)
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,631 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/metering"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DispatchInterval is how often due invitations are sent
+	DispatchInterval = 5 * time.Minute
+	// ResponsePath is the public survey link, followed by the invitation's token
+	ResponsePath = "/public/v1/surveys/"
+	// DefaultExpiryDays is how long an invitation can be answered when the
+	// survey does not say
+	DefaultExpiryDays = 14
+	// MaxSendAttempts bounds the sends of a failing invitation
+	MaxSendAttempts = 3
+	// DefaultPageSize is the page size of the invitation and response lists
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of the invitation and response lists
+	MaxPageSize = 500
+	// DefaultTrendDays is the period of a trend when none is given
+	DefaultTrendDays = 90
+	// dispatchBatch bounds the invitations sent by one dispatch pass
+	dispatchBatch = 200
+	// sendTimeout bounds the delivery of one invitation or alert
+	sendTimeout = 30 * time.Second
+	// maxComment bounds the comment of a response
+	maxComment = 2000
+)
+
+// EventDetractorResponded is published when a customer answers a survey as
+// a detractor
+const EventDetractorResponded = "surveys.detractor_responded"
+
+var (
+	// ErrInvalid wraps validation failures of surveys, responses and filters
+	ErrInvalid = errors.New("invalid request")
+	// ErrExpired is returned for an invitation link past its expiry
+	ErrExpired = errors.New("survey invitation has expired")
+)
+
+// AuthService defines the permission check used by the surveys service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Mailer delivers survey invitations and detractor alerts by email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMSSender delivers survey invitations by text message
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// ConsentChecker tells whether a contact allows being reached on a channel
+type ConsentChecker interface {
+	Allows(ctx context.Context, orgID, contactID uuid.UUID, channel string) (bool, error)
+}
+
+// SurveysService invites customers to NPS and CSAT surveys after key
+// events, collects their answers through tokenized links, trends the scores
+// per segment and follows up on detractors
+type SurveysService struct {
+	repo        repository.SurveysRepo
+	authService AuthService
+	eventBus    *events.Bus
+	mailer      Mailer
+	sms         SMSSender
+	consents    ConsentChecker
+	publicURL   string
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewSurveysService(repo repository.SurveysRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *SurveysService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SurveysService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetMailer emails invitations and detractor alerts. Without a mailer email
+// invitations are skipped.
+func (s *SurveysService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetSMSSender texts invitations. Without a sender SMS invitations are skipped.
+func (s *SurveysService) SetSMSSender(sms SMSSender) {
+	s.sms = sms
+}
+
+// SetConsentChecker skips the invitations of customers who opted out of
+// the survey's channel
+func (s *SurveysService) SetConsentChecker(consents ConsentChecker) {
+	s.consents = consents
+}
+
+// SetPublicURL is where the public survey links invitations carry are
+// served. Without it invitations are skipped.
+func (s *SurveysService) SetPublicURL(url string) {
+	s.publicURL = strings.TrimRight(url, "/")
+}
+
+// ListSurveys returns the organization's surveys
+func (s *SurveysService) ListSurveys(ctx context.Context, orgID uuid.UUID) ([]types.Survey, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListSurveys(ctx, orgID)
+}
+
+// GetSurvey returns a survey
+func (s *SurveysService) GetSurvey(ctx context.Context, orgID, id uuid.UUID) (*types.Survey, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindSurvey(ctx, orgID, id)
+}
+
+// CreateSurvey validates and creates a survey
+func (s *SurveysService) CreateSurvey(ctx context.Context, orgID, userID uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateSurvey(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateSurvey(ctx, orgID, userID, request)
+}
+
+// UpdateSurvey validates and replaces a survey. Invitations already created
+// keep their channel and send time.
+func (s *SurveysService) UpdateSurvey(ctx context.Context, orgID, userID, id uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := validateSurvey(&request); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateSurvey(ctx, orgID, userID, id, request)
+}
+
+// DeleteSurvey removes a survey with its invitations and responses
+func (s *SurveysService) DeleteSurvey(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "surveys:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteSurvey(ctx, orgID, id)
+}
+
+func validateSurvey(request *types.SurveyRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Question = strings.TrimSpace(request.Question)
+	request.Message = strings.TrimSpace(request.Message)
+	if request.Channel == "" {
+		request.Channel = types.ChannelEmail
+	}
+	if request.ExpiryDays == 0 {
+		request.ExpiryDays = DefaultExpiryDays
+	}
+
+	switch {
+	case request.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	case request.Question == "":
+		return fmt.Errorf("%w: question is required", ErrInvalid)
+	case !request.Kind.IsValid():
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalid, request.Kind)
+	case !request.Trigger.IsValid():
+		return fmt.Errorf("%w: unknown trigger %q", ErrInvalid, request.Trigger)
+	case !request.Channel.IsValid():
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalid, request.Channel)
+	case request.DelayMinutes < 0:
+		return fmt.Errorf("%w: delay cannot be negative", ErrInvalid)
+	case request.ExpiryDays < 0:
+		return fmt.Errorf("%w: expiry cannot be negative", ErrInvalid)
+	}
+
+	emails := make([]string, 0, len(request.DetractorAlertEmails))
+	for _, email := range request.DetractorAlertEmails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("%w: invalid detractor alert email %q", ErrInvalid, email)
+		}
+		emails = append(emails, email)
+	}
+	request.DetractorAlertEmails = emails
+	return nil
+}
+
+// HandleEvent invites the customer of a trigger event to every active
+// survey sent after it. Invitations go out with the next dispatch once the
+// survey's delay has passed.
+func (s *SurveysService) HandleEvent(ctx context.Context, event types.TriggerEvent) ([]types.Invitation, error) {
+	surveys, err := s.repo.ActiveSurveys(ctx, event.OrganizationID, event.Trigger)
+	if err != nil || len(surveys) == 0 {
+		return nil, err
+	}
+
+	recipient, err := s.repo.FindRecipient(ctx, event.OrganizationID, event.Trigger, event.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = s.now()
+	}
+
+	var invitations []types.Invitation
+	for _, survey := range surveys {
+		sendAt := event.OccurredAt.Add(time.Duration(survey.DelayMinutes) * time.Minute)
+		invitation, err := s.repo.CreateInvitation(ctx, types.Invitation{
+			OrganizationID: event.OrganizationID,
+			SurveyID:       survey.ID,
+			Trigger:        event.Trigger,
+			SourceID:       event.SourceID,
+			ContactID:      recipient.ContactID,
+			RecipientName:  recipient.Name,
+			Email:          recipient.Email,
+			Phone:          recipient.Phone,
+			Channel:        survey.Channel,
+			Status:         types.InvitationPending,
+			SendAt:         sendAt,
+			ExpiresAt:      sendAt.AddDate(0, 0, survey.ExpiryDays),
+		})
+		if errors.Is(err, repository.ErrDuplicate) {
+			continue
+		}
+		if err != nil {
+			return invitations, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, nil
+}
+
+// DispatchResult counts the invitations of a dispatch pass by outcome
+type DispatchResult struct {
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Dispatch sends the due invitations of every organization. A failed send
+// is retried by later passes until MaxSendAttempts.
+func (s *SurveysService) Dispatch(ctx context.Context) (*DispatchResult, error) {
+	invitations, err := s.repo.DueInvitations(ctx, s.now(), MaxSendAttempts, dispatchBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	surveys := make(map[uuid.UUID]*types.Survey)
+	result := &DispatchResult{}
+	for _, invitation := range invitations {
+		survey, ok := surveys[invitation.SurveyID]
+		if !ok {
+			if survey, err = s.repo.FindSurvey(ctx, invitation.OrganizationID, invitation.SurveyID); err != nil {
+				return result, err
+			}
+			surveys[invitation.SurveyID] = survey
+		}
+
+		status, reason := s.send(ctx, survey, invitation)
+		switch status {
+		case types.InvitationSent:
+			result.Sent++
+			continue
+		case types.InvitationFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+		if err := s.repo.MarkUnsent(ctx, invitation.ID, status, reason); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// send delivers one invitation with a fresh token, returning how it went
+// and why when it did not
+func (s *SurveysService) send(ctx context.Context, survey *types.Survey, invitation types.Invitation) (types.InvitationStatus, string) {
+	address := invitation.Email
+	if invitation.Channel == types.ChannelSMS {
+		address = invitation.Phone
+	}
+	switch {
+	case address == "":
+		return types.InvitationSkipped, fmt.Sprintf("customer has no %s address", invitation.Channel)
+	case s.publicURL == "":
+		return types.InvitationSkipped, "no public URL configured for survey links"
+	case invitation.Channel == types.ChannelEmail && s.mailer == nil:
+		return types.InvitationSkipped, "no mail server configured"
+	case invitation.Channel == types.ChannelSMS && s.sms == nil:
+		return types.InvitationSkipped, "no SMS gateway configured"
+	case !s.allows(ctx, invitation):
+		return types.InvitationSkipped, fmt.Sprintf("customer opted out of %s", invitation.Channel)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return types.InvitationFailed, err.Error()
+	}
+	link := s.publicURL + ResponsePath + token
+	intro := survey.Message
+	if intro == "" && invitation.RecipientName != "" {
+		intro = "Hello " + invitation.RecipientName + ","
+	}
+
+	sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, invitation.OrganizationID), sendTimeout)
+	defer cancel()
+	if invitation.Channel == types.ChannelSMS {
+		err = s.sms.SendSMS(sendCtx, address, strings.TrimSpace(survey.Question+" "+link))
+	} else {
+		body := fmt.Sprintf("%s\n\n%s\n\nAnswer here: %s\n", intro, survey.Question, link)
+		err = s.mailer.Send(sendCtx, address, survey.Name, strings.TrimLeft(body, "\n"))
+	}
+	if err != nil {
+		s.logger.Warn("Failed to send survey invitation", "invitation_id", invitation.ID, "channel", invitation.Channel, "error", err)
+		return types.InvitationFailed, err.Error()
+	}
+
+	if err := s.repo.MarkSent(ctx, invitation.ID, hashToken(token), s.now()); err != nil {
+		// The customer has a link that will not work; the next pass sends a
+		// working one
+		s.logger.Error("Failed to record sent survey invitation", "invitation_id", invitation.ID, "error", err)
+		return types.InvitationFailed, err.Error()
+	}
+	return types.InvitationSent, ""
+}
+
+// allows reports whether the invitation's contact may be reached on its
+// channel. Customers are only held back once they opted out, so a failed
+// check lets the invitation go.
+func (s *SurveysService) allows(ctx context.Context, invitation types.Invitation) bool {
+	if s.consents == nil || invitation.ContactID == nil {
+		return true
+	}
+	allowed, err := s.consents.Allows(ctx, invitation.OrganizationID, *invitation.ContactID, string(invitation.Channel))
+	if err != nil {
+		s.logger.Warn("Failed to check survey consent", "invitation_id", invitation.ID, "error", err)
+		return true
+	}
+	return allowed
+}
+
+// ListInvitations returns a page of the organization's invitations
+func (s *SurveysService) ListInvitations(ctx context.Context, filter types.InvitationFilter) ([]types.Invitation, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListInvitations(ctx, filter)
+}
+
+// invitation returns the invitation and survey of a link's token
+func (s *SurveysService) invitation(ctx context.Context, token string) (*types.Invitation, *types.Survey, error) {
+	invitation, err := s.repo.FindInvitationByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	survey, err := s.repo.FindSurvey(ctx, invitation.OrganizationID, invitation.SurveyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return invitation, survey, nil
+}
+
+// GetPublicSurvey returns the question an invitation link asks
+func (s *SurveysService) GetPublicSurvey(ctx context.Context, token string) (*types.PublicSurvey, error) {
+	invitation, survey, err := s.invitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	responded := invitation.Status == types.InvitationResponded
+	if !responded && !s.now().Before(invitation.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	min, max := survey.Kind.ScoreRange()
+	return &types.PublicSurvey{
+		Name:      survey.Name,
+		Kind:      survey.Kind,
+		Question:  survey.Question,
+		Message:   survey.Message,
+		MinScore:  min,
+		MaxScore:  max,
+		Responded: responded,
+	}, nil
+}
+
+// Respond records a customer's answer through an invitation link. Detractor
+// answers open a follow-up, are published and emailed to the survey's
+// alert addresses.
+func (s *SurveysService) Respond(ctx context.Context, token string, request types.ResponseRequest) (*types.Response, error) {
+	invitation, survey, err := s.invitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status == types.InvitationResponded {
+		return nil, repository.ErrAlreadyResponded
+	}
+	now := s.now()
+	if !now.Before(invitation.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	min, max := survey.Kind.ScoreRange()
+	if request.Score == nil || *request.Score < min || *request.Score > max {
+		return nil, fmt.Errorf("%w: score must be between %d and %d", ErrInvalid, min, max)
+	}
+	comment := strings.TrimSpace(request.Comment)
+	if utf8.RuneCountInString(comment) > maxComment {
+		return nil, fmt.Errorf("%w: comment is longer than %d characters", ErrInvalid, maxComment)
+	}
+
+	response := types.Response{
+		OrganizationID: invitation.OrganizationID,
+		SurveyID:       survey.ID,
+		InvitationID:   invitation.ID,
+		ContactID:      invitation.ContactID,
+		Score:          *request.Score,
+		Category:       survey.Kind.Category(*request.Score),
+		Comment:        comment,
+		RespondedAt:    now,
+	}
+	if response.Category == types.CategoryDetractor {
+		open := types.FollowUpOpen
+		response.FollowUpStatus = &open
+	}
+
+	saved, err := s.repo.SaveResponse(ctx, response)
+	if err != nil {
+		return nil, err
+	}
+	if saved.Category == types.CategoryDetractor {
+		s.onDetractor(ctx, survey, invitation, saved)
+	}
+	return saved, nil
+}
+
+// onDetractor publishes a detractor response and emails it to the survey's
+// alert addresses. Failures are logged, the response is already recorded.
+func (s *SurveysService) onDetractor(ctx context.Context, survey *types.Survey, invitation *types.Invitation, response *types.Response) {
+	event := types.DetractorEvent{
+		OrganizationID: response.OrganizationID,
+		SurveyID:       survey.ID,
+		SurveyName:     survey.Name,
+		ResponseID:     response.ID,
+		Trigger:        invitation.Trigger,
+		SourceID:       invitation.SourceID,
+		ContactID:      response.ContactID,
+		RecipientName:  invitation.RecipientName,
+		Score:          response.Score,
+		Comment:        response.Comment,
+	}
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, EventDetractorResponded, event); err != nil {
+			s.logger.Warn("Failed to publish detractor response", "response_id", response.ID, "error", err)
+		}
+	}
+
+	if s.mailer == nil || len(survey.DetractorAlertEmails) == 0 {
+		return
+	}
+	customer := invitation.RecipientName
+	if customer == "" {
+		customer = "A customer"
+	}
+	subject := fmt.Sprintf("Detractor response to %s", survey.Name)
+	body := fmt.Sprintf("%s answered %d to \"%s\" after %s.\n\n%s\n", customer, response.Score, survey.Question,
+		strings.ReplaceAll(string(invitation.Trigger), "_", " "), response.Comment)
+	for _, to := range survey.DetractorAlertEmails {
+		sendCtx, cancel := context.WithTimeout(metering.WithOrganization(ctx, response.OrganizationID), sendTimeout)
+		if err := s.mailer.Send(sendCtx, to, subject, body); err != nil {
+			s.logger.Warn("Failed to email detractor alert", "response_id", response.ID, "to", to, "error", err)
+		}
+		cancel()
+	}
+}
+
+// ListResponses returns a page of the organization's responses, e.g. the
+// detractors with an open follow-up
+func (s *SurveysService) ListResponses(ctx context.Context, filter types.ResponseFilter) ([]types.Response, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	switch filter.Category {
+	case "", types.CategoryPromoter, types.CategoryPassive, types.CategoryDetractor:
+	default:
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalid, filter.Category)
+	}
+	switch filter.FollowUpStatus {
+	case "", types.FollowUpOpen, types.FollowUpResolved:
+	default:
+		return nil, fmt.Errorf("%w: unknown follow-up status %q", ErrInvalid, filter.FollowUpStatus)
+	}
+	filter.Limit, filter.Offset = page(filter.Limit, filter.Offset)
+	return s.repo.ListResponses(ctx, filter)
+}
+
+// ResolveFollowUp closes the follow-up of a detractor response
+func (s *SurveysService) ResolveFollowUp(ctx context.Context, orgID, userID, id uuid.UUID, request types.FollowUpRequest) (*types.Response, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ResolveFollowUp(ctx, orgID, id, userID, strings.TrimSpace(request.Note), s.now())
+}
+
+// Trend scores the responses of a kind per period and segment
+func (s *SurveysService) Trend(ctx context.Context, filter types.TrendFilter) (*types.Trend, error) {
+	if err := s.authService.CheckPermission(ctx, "surveys:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Kind == "" {
+		filter.Kind = types.KindNPS
+	}
+	if filter.Interval == "" {
+		filter.Interval = types.IntervalWeek
+	}
+	switch {
+	case !filter.Kind.IsValid():
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalid, filter.Kind)
+	case !filter.Interval.IsValid():
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrInvalid, filter.Interval)
+	case !filter.SegmentBy.IsValid():
+		return nil, fmt.Errorf("%w: unknown segment %q", ErrInvalid, filter.SegmentBy)
+	}
+	if filter.To.IsZero() {
+		filter.To = s.now()
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.AddDate(0, 0, -DefaultTrendDays)
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalid)
+	}
+
+	counts, err := s.repo.Trend(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTrend(filter, counts), nil
+}
+
+// BuildTrend scores the counts of each period and segment
+func BuildTrend(filter types.TrendFilter, counts []types.TrendCounts) *types.Trend {
+	trend := &types.Trend{
+		Kind:      filter.Kind,
+		SegmentBy: filter.SegmentBy,
+		Interval:  filter.Interval,
+		From:      filter.From,
+		To:        filter.To,
+		Points:    make([]types.TrendPoint, 0, len(counts)),
+	}
+	for _, c := range counts {
+		point := types.TrendPoint{TrendCounts: c}
+		if c.Responses > 0 {
+			satisfied := float64(c.Promoters)
+			if filter.Kind == types.KindNPS {
+				satisfied -= float64(c.Detractors)
+			}
+			point.Score = round1(satisfied * 100 / float64(c.Responses))
+			point.AverageScore = round1(c.AverageScore)
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	return trend
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate survey token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
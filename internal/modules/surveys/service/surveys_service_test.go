@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/surveys/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSurveysRepo struct {
+	surveys     []types.Survey
+	recipient   *types.Recipient
+	invitations []types.Invitation
+	tokens      map[uuid.UUID]string
+	unsent      map[uuid.UUID]types.InvitationStatus
+	responses   []types.Response
+}
+
+func newFakeSurveysRepo() *fakeSurveysRepo {
+	return &fakeSurveysRepo{
+		tokens: make(map[uuid.UUID]string),
+		unsent: make(map[uuid.UUID]types.InvitationStatus),
+	}
+}
+
+func (f *fakeSurveysRepo) ListSurveys(ctx context.Context, orgID uuid.UUID) ([]types.Survey, error) {
+	return f.surveys, nil
+}
+
+func (f *fakeSurveysRepo) FindSurvey(ctx context.Context, orgID, id uuid.UUID) (*types.Survey, error) {
+	for i := range f.surveys {
+		if f.surveys[i].ID == id {
+			return &f.surveys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("survey %w", repository.ErrNotFound)
+}
+
+func (f *fakeSurveysRepo) CreateSurvey(ctx context.Context, orgID, userID uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	survey := types.Survey{ID: uuid.New(), OrganizationID: orgID, Name: request.Name, Kind: request.Kind,
+		Trigger: request.Trigger, Channel: request.Channel, ExpiryDays: request.ExpiryDays, Active: true}
+	f.surveys = append(f.surveys, survey)
+	return &survey, nil
+}
+
+func (f *fakeSurveysRepo) UpdateSurvey(ctx context.Context, orgID, userID, id uuid.UUID, request types.SurveyRequest) (*types.Survey, error) {
+	return f.FindSurvey(ctx, orgID, id)
+}
+
+func (f *fakeSurveysRepo) DeleteSurvey(ctx context.Context, orgID, id uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeSurveysRepo) ActiveSurveys(ctx context.Context, orgID uuid.UUID, trigger types.Trigger) ([]types.Survey, error) {
+	var surveys []types.Survey
+	for _, s := range f.surveys {
+		if s.Trigger == trigger && s.Active {
+			surveys = append(surveys, s)
+		}
+	}
+	return surveys, nil
+}
+
+func (f *fakeSurveysRepo) FindRecipient(ctx context.Context, orgID uuid.UUID, trigger types.Trigger, sourceID uuid.UUID) (*types.Recipient, error) {
+	if f.recipient == nil {
+		return nil, fmt.Errorf("%s source %w", trigger, repository.ErrNotFound)
+	}
+	return f.recipient, nil
+}
+
+func (f *fakeSurveysRepo) CreateInvitation(ctx context.Context, invitation types.Invitation) (*types.Invitation, error) {
+	for _, i := range f.invitations {
+		if i.SurveyID == invitation.SurveyID && i.SourceID == invitation.SourceID {
+			return nil, fmt.Errorf("survey invitation %w", repository.ErrDuplicate)
+		}
+	}
+	invitation.ID = uuid.New()
+	f.invitations = append(f.invitations, invitation)
+	return &invitation, nil
+}
+
+func (f *fakeSurveysRepo) DueInvitations(ctx context.Context, now time.Time, maxAttempts, limit int) ([]types.Invitation, error) {
+	var due []types.Invitation
+	for _, i := range f.invitations {
+		if i.Status == types.InvitationPending && !i.SendAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeSurveysRepo) invitation(id uuid.UUID) *types.Invitation {
+	for i := range f.invitations {
+		if f.invitations[i].ID == id {
+			return &f.invitations[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeSurveysRepo) MarkSent(ctx context.Context, id uuid.UUID, tokenHash string, at time.Time) error {
+	f.tokens[id] = tokenHash
+	f.invitation(id).Status = types.InvitationSent
+	return nil
+}
+
+func (f *fakeSurveysRepo) MarkUnsent(ctx context.Context, id uuid.UUID, status types.InvitationStatus, reason string) error {
+	f.unsent[id] = status
+	f.invitation(id).Status = status
+	return nil
+}
+
+func (f *fakeSurveysRepo) ListInvitations(ctx context.Context, filter types.InvitationFilter) ([]types.Invitation, error) {
+	return f.invitations, nil
+}
+
+func (f *fakeSurveysRepo) FindInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error) {
+	for id, hash := range f.tokens {
+		if hash == tokenHash {
+			return f.invitation(id), nil
+		}
+	}
+	return nil, fmt.Errorf("survey invitation %w", repository.ErrNotFound)
+}
+
+func (f *fakeSurveysRepo) SaveResponse(ctx context.Context, response types.Response) (*types.Response, error) {
+	invitation := f.invitation(response.InvitationID)
+	if invitation.Status == types.InvitationResponded {
+		return nil, repository.ErrAlreadyResponded
+	}
+	invitation.Status = types.InvitationResponded
+	response.ID = uuid.New()
+	f.responses = append(f.responses, response)
+	return &response, nil
+}
+
+func (f *fakeSurveysRepo) ListResponses(ctx context.Context, filter types.ResponseFilter) ([]types.Response, error) {
+	return f.responses, nil
+}
+
+func (f *fakeSurveysRepo) ResolveFollowUp(ctx context.Context, orgID, id, userID uuid.UUID, note string, at time.Time) (*types.Response, error) {
+	return nil, repository.ErrFollowUpClosed
+}
+
+func (f *fakeSurveysRepo) Trend(ctx context.Context, filter types.TrendFilter) ([]types.TrendCounts, error) {
+	return nil, nil
+}
+
+type sentMessage struct {
+	to, subject, body string
+}
+
+type fakeMailer struct {
+	sent []sentMessage
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, sentMessage{to: to, subject: subject, body: body})
+	return nil
+}
+
+type fakeConsents struct {
+	optedOut map[uuid.UUID]bool
+}
+
+func (c *fakeConsents) Allows(ctx context.Context, orgID, contactID uuid.UUID, channel string) (bool, error) {
+	return !c.optedOut[contactID], nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error {
+	return nil
+}
+
+var testNow = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func newTestService(repo *fakeSurveysRepo) *SurveysService {
+	s := NewSurveysService(repo, allowAll{}, nil, nil)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestKindCategory(t *testing.T) {
+	assert.Equal(t, types.CategoryDetractor, types.KindNPS.Category(6))
+	assert.Equal(t, types.CategoryPassive, types.KindNPS.Category(7))
+	assert.Equal(t, types.CategoryPassive, types.KindNPS.Category(8))
+	assert.Equal(t, types.CategoryPromoter, types.KindNPS.Category(9))
+	assert.Equal(t, types.CategoryDetractor, types.KindCSAT.Category(2))
+	assert.Equal(t, types.CategoryPassive, types.KindCSAT.Category(3))
+	assert.Equal(t, types.CategoryPromoter, types.KindCSAT.Category(4))
+}
+
+func TestCreateSurveyValidates(t *testing.T) {
+	s := newTestService(newFakeSurveysRepo())
+	orgID, userID := uuid.New(), uuid.New()
+
+	_, err := s.CreateSurvey(context.Background(), orgID, userID, types.SurveyRequest{
+		Name: "After delivery", Kind: "ces", Trigger: types.TriggerDeliveryCompleted, Question: "How did we do?",
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = s.CreateSurvey(context.Background(), orgID, userID, types.SurveyRequest{
+		Name: "After delivery", Kind: types.KindCSAT, Trigger: types.TriggerDeliveryCompleted, Question: "How did we do?",
+		DetractorAlertEmails: []string{"not an address"},
+	})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	survey, err := s.CreateSurvey(context.Background(), orgID, userID, types.SurveyRequest{
+		Name: " After delivery ", Kind: types.KindCSAT, Trigger: types.TriggerDeliveryCompleted, Question: "How did we do?",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "After delivery", survey.Name)
+	assert.Equal(t, types.ChannelEmail, survey.Channel)
+	assert.Equal(t, DefaultExpiryDays, survey.ExpiryDays)
+}
+
+func TestHandleEventInvitesOncePerSource(t *testing.T) {
+	repo := newFakeSurveysRepo()
+	orgID, contactID := uuid.New(), uuid.New()
+	repo.surveys = []types.Survey{
+		{ID: uuid.New(), Name: "NPS", Kind: types.KindNPS, Trigger: types.TriggerDealWon, Channel: types.ChannelEmail,
+			DelayMinutes: 60, ExpiryDays: 7, Active: true},
+		{ID: uuid.New(), Name: "Ticket CSAT", Kind: types.KindCSAT, Trigger: types.TriggerTicketClosed,
+			Channel: types.ChannelEmail, ExpiryDays: 7, Active: true},
+	}
+	repo.recipient = &types.Recipient{ContactID: &contactID, Name: "Ada", Email: "ada@example.test"}
+	s := newTestService(repo)
+
+	event := types.TriggerEvent{OrganizationID: orgID, Trigger: types.TriggerDealWon, SourceID: uuid.New(), OccurredAt: testNow}
+	invitations, err := s.HandleEvent(context.Background(), event)
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, repo.surveys[0].ID, invitations[0].SurveyID)
+	assert.Equal(t, testNow.Add(time.Hour), invitations[0].SendAt)
+	assert.Equal(t, testNow.Add(time.Hour).AddDate(0, 0, 7), invitations[0].ExpiresAt)
+	assert.Equal(t, "ada@example.test", invitations[0].Email)
+	assert.Equal(t, types.InvitationPending, invitations[0].Status)
+
+	invitations, err = s.HandleEvent(context.Background(), event)
+	require.NoError(t, err)
+	assert.Empty(t, invitations)
+	assert.Len(t, repo.invitations, 1)
+}
+
+func TestDispatchSendsTokenizedLinks(t *testing.T) {
+	repo := newFakeSurveysRepo()
+	survey := types.Survey{ID: uuid.New(), Name: "How was your delivery?", Kind: types.KindCSAT,
+		Question: "How satisfied are you?", Channel: types.ChannelEmail}
+	repo.surveys = []types.Survey{survey}
+	optedOut := uuid.New()
+	pending := func(channel types.Channel, email, phone string, contactID *uuid.UUID) types.Invitation {
+		return types.Invitation{ID: uuid.New(), SurveyID: survey.ID, Channel: channel, Email: email, Phone: phone,
+			ContactID: contactID, Status: types.InvitationPending, SendAt: testNow.Add(-time.Minute),
+			ExpiresAt: testNow.AddDate(0, 0, 7)}
+	}
+	repo.invitations = []types.Invitation{
+		pending(types.ChannelEmail, "ada@example.test", "", nil),
+		pending(types.ChannelEmail, "", "+15550100", nil),
+		pending(types.ChannelSMS, "", "+15550100", nil),
+		pending(types.ChannelEmail, "grace@example.test", "", &optedOut),
+	}
+	notDue := pending(types.ChannelEmail, "later@example.test", "", nil)
+	notDue.SendAt = testNow.Add(time.Hour)
+	repo.invitations = append(repo.invitations, notDue)
+
+	mailer := &fakeMailer{}
+	s := newTestService(repo)
+	s.SetMailer(mailer)
+	s.SetConsentChecker(&fakeConsents{optedOut: map[uuid.UUID]bool{optedOut: true}})
+	s.SetPublicURL("https://api.example.test/")
+
+	result, err := s.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, DispatchResult{Sent: 1, Skipped: 3}, *result)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "ada@example.test", mailer.sent[0].to)
+	assert.Equal(t, survey.Name, mailer.sent[0].subject)
+	prefix := "https://api.example.test" + ResponsePath
+	start := strings.Index(mailer.sent[0].body, prefix)
+	require.GreaterOrEqual(t, start, 0)
+	token := strings.Fields(mailer.sent[0].body[start+len(prefix):])[0]
+	assert.Equal(t, hashToken(token), repo.tokens[repo.invitations[0].ID])
+
+	assert.Equal(t, types.InvitationSkipped, repo.unsent[repo.invitations[1].ID], "no email address")
+	assert.Equal(t, types.InvitationSkipped, repo.unsent[repo.invitations[2].ID], "no SMS gateway")
+	assert.Equal(t, types.InvitationSkipped, repo.unsent[repo.invitations[3].ID], "opted out")
+	assert.Equal(t, types.InvitationPending, repo.invitations[4].Status)
+
+	public, err := s.GetPublicSurvey(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, public.MinScore)
+	assert.Equal(t, 5, public.MaxScore)
+	assert.False(t, public.Responded)
+}
+
+func TestRespondOpensDetractorFollowUp(t *testing.T) {
+	repo := newFakeSurveysRepo()
+	survey := types.Survey{ID: uuid.New(), Name: "NPS", Kind: types.KindNPS, Question: "Would you recommend us?",
+		DetractorAlertEmails: []string{"cs@example.test"}}
+	repo.surveys = []types.Survey{survey}
+	invitation := types.Invitation{ID: uuid.New(), SurveyID: survey.ID, Trigger: types.TriggerTicketClosed,
+		RecipientName: "Ada", Status: types.InvitationSent, ExpiresAt: testNow.Add(time.Hour)}
+	expired := types.Invitation{ID: uuid.New(), SurveyID: survey.ID, Status: types.InvitationSent,
+		ExpiresAt: testNow.Add(-time.Hour)}
+	repo.invitations = []types.Invitation{invitation, expired}
+	repo.tokens[invitation.ID] = hashToken("open-token")
+	repo.tokens[expired.ID] = hashToken("expired-token")
+
+	mailer := &fakeMailer{}
+	s := newTestService(repo)
+	s.SetMailer(mailer)
+	score := func(v int) *int { return &v }
+
+	_, err := s.Respond(context.Background(), "open-token", types.ResponseRequest{Score: score(11)})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = s.Respond(context.Background(), "open-token", types.ResponseRequest{})
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = s.Respond(context.Background(), "unknown-token", types.ResponseRequest{Score: score(5)})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = s.Respond(context.Background(), "expired-token", types.ResponseRequest{Score: score(5)})
+	assert.ErrorIs(t, err, ErrExpired)
+
+	response, err := s.Respond(context.Background(), "open-token", types.ResponseRequest{Score: score(3), Comment: " Slow support "})
+	require.NoError(t, err)
+	assert.Equal(t, types.CategoryDetractor, response.Category)
+	assert.Equal(t, "Slow support", response.Comment)
+	require.NotNil(t, response.FollowUpStatus)
+	assert.Equal(t, types.FollowUpOpen, *response.FollowUpStatus)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "cs@example.test", mailer.sent[0].to)
+	assert.Contains(t, mailer.sent[0].body, "Ada answered 3")
+	assert.Contains(t, mailer.sent[0].body, "Slow support")
+
+	_, err = s.Respond(context.Background(), "open-token", types.ResponseRequest{Score: score(9)})
+	assert.ErrorIs(t, err, repository.ErrAlreadyResponded)
+
+	public, err := s.GetPublicSurvey(context.Background(), "open-token")
+	require.NoError(t, err)
+	assert.True(t, public.Responded)
+}
+
+func TestRespondLeavesPromotersWithoutFollowUp(t *testing.T) {
+	repo := newFakeSurveysRepo()
+	survey := types.Survey{ID: uuid.New(), Kind: types.KindCSAT, DetractorAlertEmails: []string{"cs@example.test"}}
+	repo.surveys = []types.Survey{survey}
+	invitation := types.Invitation{ID: uuid.New(), SurveyID: survey.ID, Status: types.InvitationSent, ExpiresAt: testNow.Add(time.Hour)}
+	repo.invitations = []types.Invitation{invitation}
+	repo.tokens[invitation.ID] = hashToken("token")
+
+	mailer := &fakeMailer{}
+	s := newTestService(repo)
+	s.SetMailer(mailer)
+	five := 5
+
+	response, err := s.Respond(context.Background(), "token", types.ResponseRequest{Score: &five})
+	require.NoError(t, err)
+	assert.Equal(t, types.CategoryPromoter, response.Category)
+	assert.Nil(t, response.FollowUpStatus)
+	assert.Empty(t, mailer.sent)
+}
+
+func TestBuildTrendScores(t *testing.T) {
+	week := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	counts := []types.TrendCounts{
+		{PeriodStart: week, SegmentKey: "deal_won", Responses: 10, Promoters: 5, Passives: 3, Detractors: 2, AverageScore: 7.84},
+		{PeriodStart: week, SegmentKey: "ticket_closed", Responses: 3, Promoters: 0, Passives: 1, Detractors: 2, AverageScore: 5},
+	}
+
+	nps := BuildTrend(types.TrendFilter{Kind: types.KindNPS, SegmentBy: types.SegmentTrigger}, counts)
+	require.Len(t, nps.Points, 2)
+	assert.Equal(t, 30.0, nps.Points[0].Score)
+	assert.Equal(t, 7.8, nps.Points[0].AverageScore)
+	assert.Equal(t, -66.7, nps.Points[1].Score)
+
+	csat := BuildTrend(types.TrendFilter{Kind: types.KindCSAT}, counts[:1])
+	assert.Equal(t, 50.0, csat.Points[0].Score)
+}
@@ -0,0 +1,58 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// GatewaySender posts text messages as JSON to an SMS gateway, which talks
+// to the carrier
+type GatewaySender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewGatewaySender creates a sender for the gateway at url. A non-empty
+// token is sent as a bearer token.
+func NewGatewaySender(url, token string) *GatewaySender {
+	return &GatewaySender{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSMS posts the message for the number to the gateway
+func (g *GatewaySender) SendSMS(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(map[string]string{"to": to, "body": body})
+	if err != nil {
+		return fmt.Errorf("failed to encode text message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send text message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kind is the question a survey asks and how it is scored
+type Kind string
+
+const (
+	// KindNPS asks how likely the customer is to recommend, scored 0 to 10
+	KindNPS Kind = "nps"
+	// KindCSAT asks how satisfied the customer is, scored 1 to 5
+	KindCSAT Kind = "csat"
+)
+
+// IsValid reports whether the kind is known
+func (k Kind) IsValid() bool {
+	return k == KindNPS || k == KindCSAT
+}
+
+// ScoreRange returns the lowest and highest score of the kind
+func (k Kind) ScoreRange() (int, int) {
+	if k == KindCSAT {
+		return 1, 5
+	}
+	return 0, 10
+}
+
+// Category places a score as an NPS promoter, passive or detractor. CSAT
+// scores of 4 and 5 count as promoters and 1 and 2 as detractors.
+func (k Kind) Category(score int) Category {
+	promoter, detractor := 9, 6
+	if k == KindCSAT {
+		promoter, detractor = 4, 2
+	}
+	switch {
+	case score >= promoter:
+		return CategoryPromoter
+	case score <= detractor:
+		return CategoryDetractor
+	}
+	return CategoryPassive
+}
+
+// Trigger is the event a survey is sent after
+type Trigger string
+
+const (
+	TriggerDealWon           Trigger = "deal_won"
+	TriggerDeliveryCompleted Trigger = "delivery_completed"
+	TriggerTicketClosed      Trigger = "ticket_closed"
+)
+
+// IsValid reports whether the trigger is known
+func (t Trigger) IsValid() bool {
+	switch t {
+	case TriggerDealWon, TriggerDeliveryCompleted, TriggerTicketClosed:
+		return true
+	}
+	return false
+}
+
+// Channel is how an invitation reaches the customer
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// IsValid reports whether the channel is known
+func (c Channel) IsValid() bool {
+	return c == ChannelEmail || c == ChannelSMS
+}
+
+// Category groups responses by score
+type Category string
+
+const (
+	CategoryPromoter  Category = "promoter"
+	CategoryPassive   Category = "passive"
+	CategoryDetractor Category = "detractor"
+)
+
+// InvitationStatus is where an invitation is in its delivery
+type InvitationStatus string
+
+const (
+	InvitationPending InvitationStatus = "pending"
+	InvitationSent    InvitationStatus = "sent"
+	// InvitationFailed invitations are retried until their attempts run out
+	InvitationFailed InvitationStatus = "failed"
+	// InvitationSkipped invitations were not sent, because the customer has
+	// no address for the channel, opted out of it or nothing can deliver it
+	InvitationSkipped   InvitationStatus = "skipped"
+	InvitationResponded InvitationStatus = "responded"
+)
+
+// IsValid reports whether the status is known
+func (s InvitationStatus) IsValid() bool {
+	switch s {
+	case InvitationPending, InvitationSent, InvitationFailed, InvitationSkipped, InvitationResponded:
+		return true
+	}
+	return false
+}
+
+// FollowUpStatus tracks the follow-up of a detractor response
+type FollowUpStatus string
+
+const (
+	FollowUpOpen     FollowUpStatus = "open"
+	FollowUpResolved FollowUpStatus = "resolved"
+)
+
+// Survey asks customers one scored question after a trigger event
+type Survey struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Kind           Kind      `json:"kind"`
+	Trigger        Trigger   `json:"trigger"`
+	Channel        Channel   `json:"channel"`
+	Question       string    `json:"question"`
+	// Message introduces the question in the invitation
+	Message string `json:"message"`
+	// DelayMinutes is how long after the event the invitation is sent
+	DelayMinutes int `json:"delay_minutes"`
+	// ExpiryDays is how long after sending the invitation can be answered
+	ExpiryDays int `json:"expiry_days"`
+	// DetractorAlertEmails are emailed every detractor response
+	DetractorAlertEmails []string   `json:"detractor_alert_emails"`
+	Active               bool       `json:"active"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	CreatedBy            *uuid.UUID `json:"created_by,omitempty"`
+}
+
+// SurveyRequest creates or replaces a survey. Channel defaults to email and
+// ExpiryDays to DefaultExpiryDays.
+type SurveyRequest struct {
+	Name                 string   `json:"name"`
+	Kind                 Kind     `json:"kind"`
+	Trigger              Trigger  `json:"trigger"`
+	Channel              Channel  `json:"channel"`
+	Question             string   `json:"question"`
+	Message              string   `json:"message"`
+	DelayMinutes         int      `json:"delay_minutes"`
+	ExpiryDays           int      `json:"expiry_days"`
+	DetractorAlertEmails []string `json:"detractor_alert_emails"`
+	Active               *bool    `json:"active,omitempty"`
+}
+
+// TriggerEvent is a key event a customer may be surveyed after
+type TriggerEvent struct {
+	OrganizationID uuid.UUID
+	Trigger        Trigger
+	// SourceID is the won lead, the delivered shipment or the closed ticket
+	SourceID   uuid.UUID
+	OccurredAt time.Time
+}
+
+// Recipient is who an event's survey goes to
+type Recipient struct {
+	ContactID *uuid.UUID
+	Name      string
+	Email     string
+	Phone     string
+}
+
+// Invitation asks a customer to answer a survey
+type Invitation struct {
+	ID             uuid.UUID        `json:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	SurveyID       uuid.UUID        `json:"survey_id"`
+	Trigger        Trigger          `json:"trigger"`
+	SourceID       uuid.UUID        `json:"source_id"`
+	ContactID      *uuid.UUID       `json:"contact_id,omitempty"`
+	RecipientName  string           `json:"recipient_name"`
+	Email          string           `json:"email,omitempty"`
+	Phone          string           `json:"phone,omitempty"`
+	Channel        Channel          `json:"channel"`
+	Status         InvitationStatus `json:"status"`
+	SendAt         time.Time        `json:"send_at"`
+	ExpiresAt      time.Time        `json:"expires_at"`
+	SentAt         *time.Time       `json:"sent_at,omitempty"`
+	RespondedAt    *time.Time       `json:"responded_at,omitempty"`
+	Attempts       int              `json:"attempts"`
+	Error          string           `json:"error,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// InvitationFilter narrows the invitation list
+type InvitationFilter struct {
+	OrganizationID uuid.UUID
+	SurveyID       *uuid.UUID
+	Status         InvitationStatus
+	Limit          int
+	Offset         int
+}
+
+// Response is a customer's answer to a survey
+type Response struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	SurveyID       uuid.UUID  `json:"survey_id"`
+	InvitationID   uuid.UUID  `json:"invitation_id"`
+	ContactID      *uuid.UUID `json:"contact_id,omitempty"`
+	Score          int        `json:"score"`
+	Category       Category   `json:"category"`
+	Comment        string     `json:"comment"`
+	RespondedAt    time.Time  `json:"responded_at"`
+	// FollowUpStatus is set on detractor responses
+	FollowUpStatus     *FollowUpStatus `json:"follow_up_status,omitempty"`
+	FollowUpNote       string          `json:"follow_up_note,omitempty"`
+	FollowUpResolvedAt *time.Time      `json:"follow_up_resolved_at,omitempty"`
+	FollowUpResolvedBy *uuid.UUID      `json:"follow_up_resolved_by,omitempty"`
+}
+
+// ResponseFilter narrows the response list
+type ResponseFilter struct {
+	OrganizationID uuid.UUID
+	SurveyID       *uuid.UUID
+	Category       Category
+	FollowUpStatus FollowUpStatus
+	Limit          int
+	Offset         int
+}
+
+// ResponseRequest is a customer's answer through an invitation link
+type ResponseRequest struct {
+	Score   *int   `json:"score"`
+	Comment string `json:"comment"`
+}
+
+// FollowUpRequest resolves the follow-up of a detractor response
+type FollowUpRequest struct {
+	Note string `json:"note"`
+}
+
+// PublicSurvey is what an invitation link shows the customer
+type PublicSurvey struct {
+	Name      string `json:"name"`
+	Kind      Kind   `json:"kind"`
+	Question  string `json:"question"`
+	Message   string `json:"message"`
+	MinScore  int    `json:"min_score"`
+	MaxScore  int    `json:"max_score"`
+	Responded bool   `json:"responded"`
+}
+
+// DetractorEvent is published when a customer answers as a detractor
+type DetractorEvent struct {
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	SurveyID       uuid.UUID  `json:"survey_id"`
+	SurveyName     string     `json:"survey_name"`
+	ResponseID     uuid.UUID  `json:"response_id"`
+	Trigger        Trigger    `json:"trigger"`
+	SourceID       uuid.UUID  `json:"source_id"`
+	ContactID      *uuid.UUID `json:"contact_id,omitempty"`
+	RecipientName  string     `json:"recipient_name"`
+	Score          int        `json:"score"`
+	Comment        string     `json:"comment"`
+}
+
+// Segment is what a score trend is split by
+type Segment string
+
+const (
+	SegmentNone           Segment = ""
+	SegmentSurvey         Segment = "survey"
+	SegmentTrigger        Segment = "trigger"
+	SegmentChannel        Segment = "channel"
+	SegmentContactSegment Segment = "contact_segment"
+)
+
+// IsValid reports whether the segment is known
+func (s Segment) IsValid() bool {
+	switch s {
+	case SegmentNone, SegmentSurvey, SegmentTrigger, SegmentChannel, SegmentContactSegment:
+		return true
+	}
+	return false
+}
+
+// Interval is the period a score trend is bucketed by
+type Interval string
+
+const (
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// IsValid reports whether the interval is known
+func (i Interval) IsValid() bool {
+	return i == IntervalWeek || i == IntervalMonth
+}
+
+// TrendFilter selects the responses of a score trend. Responses of one kind
+// are scored together, NPS and CSAT never mix.
+type TrendFilter struct {
+	OrganizationID uuid.UUID
+	Kind           Kind
+	SurveyID       *uuid.UUID
+	SegmentBy      Segment
+	Interval       Interval
+	From           time.Time
+	To             time.Time
+}
+
+// TrendCounts are the responses of one period and segment. Contacts in no
+// contact segment are counted under an empty segment.
+type TrendCounts struct {
+	PeriodStart  time.Time `json:"period_start"`
+	SegmentKey   string    `json:"segment_key,omitempty"`
+	SegmentName  string    `json:"segment_name,omitempty"`
+	Responses    int       `json:"responses"`
+	Promoters    int       `json:"promoters"`
+	Passives     int       `json:"passives"`
+	Detractors   int       `json:"detractors"`
+	AverageScore float64   `json:"average_score"`
+}
+
+// TrendPoint is the score of one period and segment: NPS is the percentage
+// of promoters less the percentage of detractors, from -100 to 100, and CSAT
+// the percentage of satisfied customers, from 0 to 100
+type TrendPoint struct {
+	TrendCounts
+	Score float64 `json:"score"`
+}
+
+// Trend is a survey score over time, split by segment
+type Trend struct {
+	Kind      Kind         `json:"kind"`
+	SegmentBy Segment      `json:"segment_by,omitempty"`
+	Interval  Interval     `json:"interval"`
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	Points    []TrendPoint `json:"points"`
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/training/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/training/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/training/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxExpiryWindowDays bounds the within_days of the expiry list
+const maxExpiryWindowDays = 730
+
+// TrainingHandler handles certification types, employee certifications,
+// their expiry, and the certifications activities require
+type TrainingHandler struct {
+	service *service.TrainingService
+}
+
+func NewTrainingHandler(service *service.TrainingService) *TrainingHandler {
+	return &TrainingHandler{service: service}
+}
+
+func (h *TrainingHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/certification-types", h.ListTypes)
+	router.POST("/api/certification-types", h.CreateType)
+	router.GET("/api/certification-types/:id", h.GetType)
+	router.PUT("/api/certification-types/:id", h.UpdateType)
+
+	router.GET("/api/certifications", h.ListCertifications)
+	router.POST("/api/certifications", h.RecordCertification)
+	router.GET("/api/certifications/:id", h.GetCertification)
+	router.POST("/api/certifications/:id/revoke", h.RevokeCertification)
+
+	router.GET("/api/certification-expiries", h.ListExpiring)
+	router.POST("/api/certification-expiries", h.CheckExpiries)
+
+	router.GET("/api/certification-requirements", h.ListRequirements)
+	router.PUT("/api/certification-requirements/:activity", h.SetRequirements)
+	router.GET("/api/certification-qualification", h.Qualification)
+}
+
+// ListTypes handles GET /api/certification-types, with active=true to
+// leave out archived types
+func (h *TrainingHandler) ListTypes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	certTypes, err := h.service.ListTypes(r.Context(), authCtx.OrganizationID, r.URL.Query().Get("active") == "true")
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, certTypes)
+}
+
+// CreateType handles POST /api/certification-types
+func (h *TrainingHandler) CreateType(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CertificationTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	certType, err := h.service.CreateType(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, certType)
+}
+
+// GetType handles GET /api/certification-types/:id
+func (h *TrainingHandler) GetType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid certification type ID", http.StatusBadRequest)
+		return
+	}
+
+	certType, err := h.service.GetType(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, certType)
+}
+
+// UpdateType handles PUT /api/certification-types/:id
+func (h *TrainingHandler) UpdateType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid certification type ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CertificationTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	certType, err := h.service.UpdateType(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, certType)
+}
+
+// ListCertifications handles GET /api/certifications, filtered by
+// employee_id, certification_type_id, category and status
+func (h *TrainingHandler) ListCertifications(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.CertificationFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Category:       types.Category(q.Get("category")),
+		Status:         types.CertificationStatus(q.Get("status")),
+	}
+	if !queryUUIDs(w, r, map[string]**uuid.UUID{
+		"employee_id":           &filter.EmployeeID,
+		"certification_type_id": &filter.CertificationTypeID,
+	}) {
+		return
+	}
+
+	certs, err := h.service.ListCertifications(r.Context(), filter)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, certs)
+}
+
+// RecordCertification handles POST /api/certifications
+func (h *TrainingHandler) RecordCertification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.CertificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cert, err := h.service.RecordCertification(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cert)
+}
+
+// GetCertification handles GET /api/certifications/:id
+func (h *TrainingHandler) GetCertification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid certification ID", http.StatusBadRequest)
+		return
+	}
+
+	cert, err := h.service.GetCertification(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cert)
+}
+
+// RevokeCertification handles POST /api/certifications/:id/revoke
+func (h *TrainingHandler) RevokeCertification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid certification ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cert, err := h.service.RevokeCertification(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cert)
+}
+
+// ListExpiring handles GET /api/certification-expiries, listing the
+// unrevoked certifications expiring within_days from today, 30 by default.
+// Already expired certifications are included.
+func (h *TrainingHandler) ListExpiring(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	days := service.DefaultAlertDays
+	if v := r.URL.Query().Get("within_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxExpiryWindowDays {
+			http.Error(w, "Invalid within_days", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	certs, err := h.service.ListExpiring(r.Context(), authCtx.OrganizationID, days)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, certs)
+}
+
+// CheckExpiries handles POST /api/certification-expiries, raising the
+// expiry alerts due without waiting for the scheduled check
+func (h *TrainingHandler) CheckExpiries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CheckExpiries(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ListRequirements handles GET /api/certification-requirements
+func (h *TrainingHandler) ListRequirements(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	requirements, err := h.service.ListRequirements(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requirements)
+}
+
+// SetRequirements handles PUT /api/certification-requirements/:activity
+func (h *TrainingHandler) SetRequirements(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.RequirementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requirement, err := h.service.SetRequirements(r.Context(), authCtx.OrganizationID, authCtx.UserID,
+		types.Activity(ps.ByName("activity")), req)
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requirement)
+}
+
+// Qualification handles GET /api/certification-qualification, telling
+// whether employee_id may be assigned activity
+func (h *TrainingHandler) Qualification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	employeeID, err := uuid.Parse(r.URL.Query().Get("employee_id"))
+	if err != nil {
+		http.Error(w, "Invalid employee_id", http.StatusBadRequest)
+		return
+	}
+
+	qualification, err := h.service.Qualification(r.Context(), authCtx.OrganizationID, employeeID,
+		types.Activity(r.URL.Query().Get("activity")))
+	if err != nil {
+		writeTrainingError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, qualification)
+}
+
+func queryUUIDs(w http.ResponseWriter, r *http.Request, params map[string]**uuid.UUID) bool {
+	q := r.URL.Query()
+	for name, dest := range params {
+		if v := q.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return false
+			}
+			*dest = &id
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeTrainingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/training/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeExpiryAlerts = "training.expiry_alerts"
+
+// ExpiryAlertJobHandler handles queued certification expiry checks
+type ExpiryAlertJobHandler struct {
+	trainingService *service.TrainingService
+}
+
+func NewExpiryAlertJobHandler(trainingService *service.TrainingService) *ExpiryAlertJobHandler {
+	return &ExpiryAlertJobHandler{
+		trainingService: trainingService,
+	}
+}
+
+// Handle processes a certification expiry check job
+func (h *ExpiryAlertJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	if err := h.trainingService.CheckAll(ctx); err != nil {
+		return fmt.Errorf("failed to check certification expiries: %w", err)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *ExpiryAlertJobHandler) JobType() string {
+	return JobTypeExpiryAlerts
+}
+
+// Scheduler checks certifications on a fixed interval, so holders and
+// their managers are alerted before certifications lapse
+type Scheduler struct {
+	handler     *ExpiryAlertJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *ExpiryAlertJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start checks certifications every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeExpiryAlerts, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeExpiryAlerts, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled certification expiry check failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule certification expiry check", "error", err)
+	}
+}
@@ -0,0 +1,504 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/training/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a certification type, certification or
+	// employee does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a certification type code is already used
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when revoking a certification that is
+	// already revoked
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// TrainingRepo defines the interface for training repository operations
+type TrainingRepo interface {
+	ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error)
+	ListTypes(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.CertificationType, error)
+	FindType(ctx context.Context, orgID, id uuid.UUID) (*types.CertificationType, error)
+	CreateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error)
+	UpdateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error)
+	ListCertifications(ctx context.Context, filter types.CertificationFilter) ([]types.Certification, error)
+	FindCertification(ctx context.Context, orgID, id uuid.UUID) (*types.Certification, error)
+	CreateCertification(ctx context.Context, userID *uuid.UUID, cert types.Certification) (*types.Certification, error)
+	RevokeCertification(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reason string, at time.Time) (*types.Certification, error)
+	ListRequirements(ctx context.Context, orgID uuid.UUID) ([]types.Requirement, error)
+	SetRequirements(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity, typeIDs []uuid.UUID) (*types.Requirement, error)
+	MissingCertifications(ctx context.Context, orgID, employeeID uuid.UUID, activity types.Activity, day time.Time) ([]types.CertificationType, error)
+	EmployeeForUser(ctx context.Context, orgID, userID uuid.UUID) (uuid.UUID, error)
+	ClaimExpiryAlerts(ctx context.Context, orgID uuid.UUID, day time.Time, at time.Time) ([]types.ExpiryAlert, error)
+}
+
+// TrainingRepository stores certification types, employee certifications
+// and the certifications activities require
+type TrainingRepository struct {
+	db *sql.DB
+}
+
+// Ensure TrainingRepository implements TrainingRepo interface
+var _ TrainingRepo = &TrainingRepository{}
+
+func NewTrainingRepository(db *sql.DB) *TrainingRepository {
+	return &TrainingRepository{db: db}
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// ListOrganizationIDs returns the organizations holding unrevoked
+// certifications that expire
+func (r *TrainingRepository) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT organization_id FROM employee_certifications
+		WHERE revoked_at IS NULL AND alerted_at IS NULL AND expires_on IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+const typeColumns = `id, organization_id, code, name, category, validity_months, alert_days, active, created_at, updated_at`
+
+func scanType(row interface{ Scan(...interface{}) error }) (*types.CertificationType, error) {
+	var t types.CertificationType
+	var validity sql.NullInt64
+	if err := row.Scan(&t.ID, &t.OrganizationID, &t.Code, &t.Name, &t.Category, &validity, &t.AlertDays,
+		&t.Active, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if validity.Valid {
+		months := int(validity.Int64)
+		t.ValidityMonths = &months
+	}
+	return &t, nil
+}
+
+func scanTypes(rows *sql.Rows) ([]types.CertificationType, error) {
+	defer rows.Close()
+	certTypes := []types.CertificationType{}
+	for rows.Next() {
+		t, err := scanType(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan certification type: %w", err)
+		}
+		certTypes = append(certTypes, *t)
+	}
+	return certTypes, rows.Err()
+}
+
+func (r *TrainingRepository) ListTypes(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.CertificationType, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+typeColumns+` FROM certification_types
+		WHERE organization_id = $1 AND (active OR NOT $2)
+		ORDER BY category, name, id
+	`, orgID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certification types: %w", err)
+	}
+	return scanTypes(rows)
+}
+
+func (r *TrainingRepository) FindType(ctx context.Context, orgID, id uuid.UUID) (*types.CertificationType, error) {
+	t, err := scanType(r.db.QueryRowContext(ctx, `
+		SELECT `+typeColumns+` FROM certification_types WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("certification type %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find certification type: %w", err)
+	}
+	return t, nil
+}
+
+func (r *TrainingRepository) CreateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error) {
+	t, err := scanType(r.db.QueryRowContext(ctx, `
+		INSERT INTO certification_types (
+			organization_id, code, name, category, validity_months, alert_days, active, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING `+typeColumns,
+		certType.OrganizationID, certType.Code, certType.Name, string(certType.Category), certType.ValidityMonths,
+		certType.AlertDays, certType.Active, userID))
+	if err != nil {
+		if isUniqueViolation(err, "certification_types_code_key") {
+			return nil, fmt.Errorf("certification type %s %w", certType.Code, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to create certification type: %w", err)
+	}
+	return t, nil
+}
+
+// UpdateType replaces a certification type. Certifications already
+// recorded keep their expiry dates.
+func (r *TrainingRepository) UpdateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error) {
+	t, err := scanType(r.db.QueryRowContext(ctx, `
+		UPDATE certification_types SET
+			code = $3, name = $4, category = $5, validity_months = $6, alert_days = $7, active = $8,
+			updated_at = now(), updated_by = $9
+		WHERE organization_id = $1 AND id = $2
+		RETURNING `+typeColumns,
+		certType.OrganizationID, certType.ID, certType.Code, certType.Name, string(certType.Category),
+		certType.ValidityMonths, certType.AlertDays, certType.Active, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("certification type %w", ErrNotFound)
+		}
+		if isUniqueViolation(err, "certification_types_code_key") {
+			return nil, fmt.Errorf("certification type %s %w", certType.Code, ErrDuplicate)
+		}
+		return nil, fmt.Errorf("failed to update certification type: %w", err)
+	}
+	return t, nil
+}
+
+const certificationColumns = `c.id, c.organization_id, c.employee_id, e.name, c.certification_type_id, t.code, t.name,
+	t.category, t.alert_days, COALESCE(c.certificate_number, ''), COALESCE(c.issuer, ''), c.issued_on, c.expires_on,
+	COALESCE(c.notes, ''), c.revoked_at, COALESCE(c.revoked_reason, ''), c.alerted_at, c.created_at, c.created_by`
+
+const certificationJoins = `
+	FROM employee_certifications c
+	JOIN employees e ON e.id = c.employee_id
+	JOIN certification_types t ON t.id = c.certification_type_id`
+
+func scanCertification(row interface{ Scan(...interface{}) error }) (*types.Certification, error) {
+	var c types.Certification
+	var expiresOn, revokedAt, alertedAt sql.NullTime
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&c.ID, &c.OrganizationID, &c.EmployeeID, &c.EmployeeName, &c.CertificationTypeID,
+		&c.TypeCode, &c.TypeName, &c.Category, &c.AlertDays, &c.CertificateNumber, &c.Issuer, &c.IssuedOn,
+		&expiresOn, &c.Notes, &revokedAt, &c.RevokedReason, &alertedAt, &c.CreatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	if expiresOn.Valid {
+		c.ExpiresOn = &expiresOn.Time
+	}
+	if revokedAt.Valid {
+		c.RevokedAt = &revokedAt.Time
+	}
+	if alertedAt.Valid {
+		c.AlertedAt = &alertedAt.Time
+	}
+	c.CreatedBy = nullUUID(createdBy)
+	return &c, nil
+}
+
+// ListCertifications returns the certifications matching the filter, those
+// expiring soonest first. Statuses are left for the caller to set.
+func (r *TrainingRepository) ListCertifications(ctx context.Context, filter types.CertificationFilter) ([]types.Certification, error) {
+	where := []string{"c.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.EmployeeID != nil {
+		args = append(args, *filter.EmployeeID)
+		where = append(where, fmt.Sprintf("c.employee_id = $%d", len(args)))
+	}
+	if filter.CertificationTypeID != nil {
+		args = append(args, *filter.CertificationTypeID)
+		where = append(where, fmt.Sprintf("c.certification_type_id = $%d", len(args)))
+	}
+	if filter.Category != "" {
+		args = append(args, string(filter.Category))
+		where = append(where, fmt.Sprintf("t.category = $%d", len(args)))
+	}
+	if filter.ExpiringBefore != nil {
+		args = append(args, *filter.ExpiringBefore)
+		where = append(where, fmt.Sprintf("c.revoked_at IS NULL AND c.expires_on <= $%d", len(args)))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+certificationColumns+certificationJoins+`
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY c.expires_on NULLS LAST, e.name, t.name, c.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certifications: %w", err)
+	}
+	defer rows.Close()
+
+	certs := []types.Certification{}
+	for rows.Next() {
+		c, err := scanCertification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan certification: %w", err)
+		}
+		certs = append(certs, *c)
+	}
+	return certs, rows.Err()
+}
+
+func (r *TrainingRepository) FindCertification(ctx context.Context, orgID, id uuid.UUID) (*types.Certification, error) {
+	c, err := scanCertification(r.db.QueryRowContext(ctx, `SELECT `+certificationColumns+certificationJoins+`
+		WHERE c.organization_id = $1 AND c.id = $2`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("certification %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find certification: %w", err)
+	}
+	return c, nil
+}
+
+// CreateCertification records a certification of an employee of the
+// organization
+func (r *TrainingRepository) CreateCertification(ctx context.Context, userID *uuid.UUID, cert types.Certification) (*types.Certification, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO employee_certifications (
+			organization_id, employee_id, certification_type_id, certificate_number, issuer,
+			issued_on, expires_on, notes, created_by, updated_by
+		)
+		SELECT $1, e.id, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9, $9
+		FROM employees e
+		WHERE e.id = $2 AND e.organization_id = $1 AND e.deleted_at IS NULL
+		RETURNING id
+	`, cert.OrganizationID, cert.EmployeeID, cert.CertificationTypeID, cert.CertificateNumber, cert.Issuer,
+		cert.IssuedOn, cert.ExpiresOn, cert.Notes, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("employee %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to create certification: %w", err)
+	}
+	return r.FindCertification(ctx, cert.OrganizationID, id)
+}
+
+func (r *TrainingRepository) RevokeCertification(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reason string, at time.Time) (*types.Certification, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE employee_certifications SET
+			revoked_at = $3, revoked_reason = NULLIF($4, ''), updated_at = $3, updated_by = $5
+		WHERE organization_id = $1 AND id = $2 AND revoked_at IS NULL
+	`, orgID, id, at, reason, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke certification: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.FindCertification(ctx, orgID, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("certification is already revoked: %w", ErrInvalidState)
+	}
+	return r.FindCertification(ctx, orgID, id)
+}
+
+// ListRequirements returns the certification types each activity requires,
+// for the activities that require any
+func (r *TrainingRepository) ListRequirements(ctx context.Context, orgID uuid.UUID) ([]types.Requirement, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT q.activity, `+prefixed("t.", typeColumns)+`
+		FROM certification_requirements q
+		JOIN certification_types t ON t.id = q.certification_type_id
+		WHERE q.organization_id = $1
+		ORDER BY q.activity, t.name, t.id
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certification requirements: %w", err)
+	}
+	defer rows.Close()
+
+	requirements := []types.Requirement{}
+	for rows.Next() {
+		var activity types.Activity
+		var t types.CertificationType
+		var validity sql.NullInt64
+		if err := rows.Scan(&activity, &t.ID, &t.OrganizationID, &t.Code, &t.Name, &t.Category, &validity,
+			&t.AlertDays, &t.Active, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan certification requirement: %w", err)
+		}
+		if validity.Valid {
+			months := int(validity.Int64)
+			t.ValidityMonths = &months
+		}
+		if n := len(requirements); n == 0 || requirements[n-1].Activity != activity {
+			requirements = append(requirements, types.Requirement{Activity: activity})
+		}
+		last := &requirements[len(requirements)-1]
+		last.CertificationTypes = append(last.CertificationTypes, t)
+	}
+	return requirements, rows.Err()
+}
+
+func prefixed(prefix, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, p := range parts {
+		parts[i] = prefix + p
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetRequirements replaces the certification types an activity requires
+func (r *TrainingRepository) SetRequirements(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity, typeIDs []uuid.UUID) (*types.Requirement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM certification_requirements WHERE organization_id = $1 AND activity = $2
+	`, orgID, string(activity)); err != nil {
+		return nil, fmt.Errorf("failed to clear certification requirements: %w", err)
+	}
+
+	for _, typeID := range typeIDs {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO certification_requirements (organization_id, activity, certification_type_id, created_by)
+			SELECT $1, $2, id, $4 FROM certification_types WHERE organization_id = $1 AND id = $3
+			ON CONFLICT DO NOTHING
+		`, orgID, string(activity), typeID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add certification requirement: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM certification_types WHERE organization_id = $1 AND id = $2)
+			`, orgID, typeID).Scan(&exists); err != nil {
+				return nil, fmt.Errorf("failed to check certification type: %w", err)
+			}
+			if !exists {
+				return nil, fmt.Errorf("certification type %s %w", typeID, ErrNotFound)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit certification requirements: %w", err)
+	}
+
+	requirements, err := r.ListRequirements(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range requirements {
+		if req.Activity == activity {
+			return &req, nil
+		}
+	}
+	return &types.Requirement{Activity: activity, CertificationTypes: []types.CertificationType{}}, nil
+}
+
+// MissingCertifications returns the certification types the activity
+// requires that the employee holds no unrevoked certification of valid on
+// the day
+func (r *TrainingRepository) MissingCertifications(ctx context.Context, orgID, employeeID uuid.UUID, activity types.Activity, day time.Time) ([]types.CertificationType, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+prefixed("t.", typeColumns)+`
+		FROM certification_requirements q
+		JOIN certification_types t ON t.id = q.certification_type_id
+		WHERE q.organization_id = $1 AND q.activity = $3
+			AND NOT EXISTS (
+				SELECT 1 FROM employee_certifications c
+				WHERE c.organization_id = $1 AND c.employee_id = $2
+					AND c.certification_type_id = q.certification_type_id
+					AND c.revoked_at IS NULL
+					AND c.issued_on <= $4
+					AND (c.expires_on IS NULL OR c.expires_on >= $4)
+			)
+		ORDER BY t.name, t.id
+	`, orgID, employeeID, string(activity), day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check certifications: %w", err)
+	}
+	return scanTypes(rows)
+}
+
+// EmployeeForUser returns the employee record of a user
+func (r *TrainingRepository) EmployeeForUser(ctx context.Context, orgID, userID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM employees
+		WHERE organization_id = $1 AND user_id = $2 AND deleted_at IS NULL
+		ORDER BY active DESC, created_at
+		LIMIT 1
+	`, orgID, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("employee of user %s %w", userID, ErrNotFound)
+		}
+		return uuid.Nil, fmt.Errorf("failed to find employee of user: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimExpiryAlerts marks the unrevoked certifications that entered their
+// alert window by the day as alerted, and returns them. Certifications
+// already renewed by a later one of the same type are skipped, and marking
+// them in one statement lets every instance run the check.
+func (r *TrainingRepository) ClaimExpiryAlerts(ctx context.Context, orgID uuid.UUID, day time.Time, at time.Time) ([]types.ExpiryAlert, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE employee_certifications c SET alerted_at = $3
+			FROM certification_types t
+			WHERE t.id = c.certification_type_id
+				AND c.organization_id = $1
+				AND c.revoked_at IS NULL
+				AND c.alerted_at IS NULL
+				AND c.expires_on IS NOT NULL
+				AND c.expires_on <= $2::date + t.alert_days
+				AND NOT EXISTS (
+					SELECT 1 FROM employee_certifications n
+					WHERE n.employee_id = c.employee_id
+						AND n.certification_type_id = c.certification_type_id
+						AND n.revoked_at IS NULL
+						AND n.id <> c.id
+						AND (n.expires_on IS NULL OR n.expires_on > c.expires_on)
+				)
+			RETURNING c.id, c.organization_id, c.employee_id, c.certification_type_id, t.name, c.expires_on
+		)
+		SELECT cl.id, cl.organization_id, cl.employee_id, e.name, e.user_id, m.user_id,
+			cl.certification_type_id, cl.name, cl.expires_on
+		FROM claimed cl
+		JOIN employees e ON e.id = cl.employee_id
+		LEFT JOIN employees m ON m.id = e.parent_id
+		ORDER BY cl.expires_on, e.name
+	`, orgID, day, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim certification expiry alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []types.ExpiryAlert{}
+	for rows.Next() {
+		var a types.ExpiryAlert
+		var userID, managerUserID uuid.NullUUID
+		if err := rows.Scan(&a.CertificationID, &a.OrganizationID, &a.EmployeeID, &a.EmployeeName, &userID,
+			&managerUserID, &a.CertificationTypeID, &a.TypeName, &a.ExpiresOn); err != nil {
+			return nil, fmt.Errorf("failed to scan certification expiry alert: %w", err)
+		}
+		a.EmployeeUserID = nullUUID(userID)
+		a.ManagerUserID = nullUUID(managerUserID)
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/training/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/training/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CheckInterval is how often certifications are checked for expiry
+	CheckInterval = 6 * time.Hour
+	// DefaultAlertDays is the alert window of certification types that do
+	// not set one
+	DefaultAlertDays = 30
+	// MaxAlertDays bounds the alert window of certification types
+	MaxAlertDays = 365
+	// MaxValidityMonths bounds the validity of certification types
+	MaxValidityMonths = 240
+	// MaxRequiredTypes bounds the certification types an activity requires
+	MaxRequiredTypes = 20
+	// EventCertified is published when a certification is recorded
+	EventCertified = "training.certified"
+	// EventCertificationRevoked is published when a certification is revoked
+	EventCertificationRevoked = "training.certification_revoked"
+	// EventCertificationExpiring is published once for each certification
+	// entering its alert window, for notifying the holder and their manager
+	EventCertificationExpiring = "training.certification_expiring"
+)
+
+var (
+	// ErrInvalid wraps validation failures of certification types,
+	// certifications and requirements
+	ErrInvalid = errors.New("invalid request")
+	// ErrNotQualified is returned when an employee lacks an active
+	// certification an activity requires
+	ErrNotQualified = errors.New("not qualified")
+)
+
+// AuthService defines the permission check used by the training service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// TrainingService tracks employee certifications and their expiry, and
+// tells whether employees hold the certifications QC inspections and
+// hazmat routes require
+type TrainingService struct {
+	repo        repository.TrainingRepo
+	authService AuthService
+	eventBus    *events.Bus
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewTrainingService(repo repository.TrainingRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *TrainingService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &TrainingService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// today is the current date at midnight UTC
+func (s *TrainingService) today() time.Time {
+	return s.now().UTC().Truncate(24 * time.Hour)
+}
+
+func (s *TrainingService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish training event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListTypes returns the certification types of the organization
+func (s *TrainingService) ListTypes(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.CertificationType, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListTypes(ctx, orgID, activeOnly)
+}
+
+// GetType returns a certification type
+func (s *TrainingService) GetType(ctx context.Context, orgID, id uuid.UUID) (*types.CertificationType, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindType(ctx, orgID, id)
+}
+
+func validateType(request types.CertificationTypeRequest) (types.CertificationType, error) {
+	certType := types.CertificationType{
+		Code:           strings.TrimSpace(request.Code),
+		Name:           strings.TrimSpace(request.Name),
+		Category:       request.Category,
+		ValidityMonths: request.ValidityMonths,
+		AlertDays:      DefaultAlertDays,
+		Active:         true,
+	}
+	if certType.Code == "" || certType.Name == "" {
+		return certType, fmt.Errorf("%w: code and name are required", ErrInvalid)
+	}
+	if certType.Category == "" {
+		certType.Category = types.CategoryOther
+	}
+	if !certType.Category.IsValid() {
+		return certType, fmt.Errorf("%w: unknown category %q", ErrInvalid, certType.Category)
+	}
+	if v := certType.ValidityMonths; v != nil && (*v <= 0 || *v > MaxValidityMonths) {
+		return certType, fmt.Errorf("%w: validity_months must be between 1 and %d", ErrInvalid, MaxValidityMonths)
+	}
+	if request.AlertDays != nil {
+		if *request.AlertDays < 0 || *request.AlertDays > MaxAlertDays {
+			return certType, fmt.Errorf("%w: alert_days must be between 0 and %d", ErrInvalid, MaxAlertDays)
+		}
+		certType.AlertDays = *request.AlertDays
+	}
+	if request.Active != nil {
+		certType.Active = *request.Active
+	}
+	return certType, nil
+}
+
+// CreateType validates and creates a certification type
+func (s *TrainingService) CreateType(ctx context.Context, orgID, userID uuid.UUID, request types.CertificationTypeRequest) (*types.CertificationType, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	certType, err := validateType(request)
+	if err != nil {
+		return nil, err
+	}
+	certType.OrganizationID = orgID
+	return s.repo.CreateType(ctx, &userID, certType)
+}
+
+// UpdateType validates and replaces a certification type. Certifications
+// already recorded keep their expiry dates.
+func (s *TrainingService) UpdateType(ctx context.Context, orgID, userID, id uuid.UUID, request types.CertificationTypeRequest) (*types.CertificationType, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	certType, err := validateType(request)
+	if err != nil {
+		return nil, err
+	}
+	certType.OrganizationID = orgID
+	certType.ID = id
+	return s.repo.UpdateType(ctx, &userID, certType)
+}
+
+// ListCertifications returns the certifications matching the filter with
+// their status today
+func (s *TrainingService) ListCertifications(ctx context.Context, filter types.CertificationFilter) ([]types.Certification, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	if filter.Category != "" && !filter.Category.IsValid() {
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalid, filter.Category)
+	}
+
+	certs, err := s.repo.ListCertifications(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	today := s.today()
+	result := make([]types.Certification, 0, len(certs))
+	for _, c := range certs {
+		c.Status = c.StatusOn(today)
+		if filter.Status == "" || c.Status == filter.Status {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// GetCertification returns a certification with its status today
+func (s *TrainingService) GetCertification(ctx context.Context, orgID, id uuid.UUID) (*types.Certification, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	cert, err := s.repo.FindCertification(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	cert.Status = cert.StatusOn(s.today())
+	return cert, nil
+}
+
+// ListExpiring returns the unrevoked certifications expiring within days
+// from today, including those already expired, soonest first
+func (s *TrainingService) ListExpiring(ctx context.Context, orgID uuid.UUID, days int) ([]types.Certification, error) {
+	before := s.today().AddDate(0, 0, days)
+	return s.ListCertifications(ctx, types.CertificationFilter{
+		OrganizationID: orgID,
+		ExpiringBefore: &before,
+	})
+}
+
+// RecordCertification records a certification of an employee. Without an
+// expiry date, it expires after the validity of its type.
+func (s *TrainingService) RecordCertification(ctx context.Context, orgID, userID uuid.UUID, request types.CertificationRequest) (*types.Certification, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if request.EmployeeID == uuid.Nil || request.CertificationTypeID == uuid.Nil {
+		return nil, fmt.Errorf("%w: employee_id and certification_type_id are required", ErrInvalid)
+	}
+	if request.IssuedOn.IsZero() {
+		return nil, fmt.Errorf("%w: issued_on is required", ErrInvalid)
+	}
+	issuedOn := request.IssuedOn.UTC().Truncate(24 * time.Hour)
+	if issuedOn.After(s.today()) {
+		return nil, fmt.Errorf("%w: issued_on cannot be in the future", ErrInvalid)
+	}
+
+	certType, err := s.repo.FindType(ctx, orgID, request.CertificationTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if !certType.Active {
+		return nil, fmt.Errorf("%w: certification type %s is archived", ErrInvalid, certType.Code)
+	}
+
+	expiresOn := request.ExpiresOn
+	if expiresOn != nil {
+		day := expiresOn.UTC().Truncate(24 * time.Hour)
+		if day.Before(issuedOn) {
+			return nil, fmt.Errorf("%w: expires_on cannot be before issued_on", ErrInvalid)
+		}
+		expiresOn = &day
+	} else if certType.ValidityMonths != nil {
+		day := issuedOn.AddDate(0, *certType.ValidityMonths, 0)
+		expiresOn = &day
+	}
+
+	cert, err := s.repo.CreateCertification(ctx, &userID, types.Certification{
+		OrganizationID:      orgID,
+		EmployeeID:          request.EmployeeID,
+		CertificationTypeID: certType.ID,
+		CertificateNumber:   strings.TrimSpace(request.CertificateNumber),
+		Issuer:              strings.TrimSpace(request.Issuer),
+		IssuedOn:            issuedOn,
+		ExpiresOn:           expiresOn,
+		Notes:               strings.TrimSpace(request.Notes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cert.Status = cert.StatusOn(s.today())
+
+	s.publishEvent(ctx, EventCertified, cert)
+	return cert, nil
+}
+
+// RevokeCertification revokes a certification, which stops qualifying its
+// holder straight away
+func (s *TrainingService) RevokeCertification(ctx context.Context, orgID, userID, id uuid.UUID, request types.RevokeRequest) (*types.Certification, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required to revoke a certification", ErrInvalid)
+	}
+
+	cert, err := s.repo.RevokeCertification(ctx, orgID, id, &userID, reason, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	cert.Status = cert.StatusOn(s.today())
+
+	s.publishEvent(ctx, EventCertificationRevoked, cert)
+	return cert, nil
+}
+
+// ListRequirements returns the certification types each activity requires
+func (s *TrainingService) ListRequirements(ctx context.Context, orgID uuid.UUID) ([]types.Requirement, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListRequirements(ctx, orgID)
+}
+
+// SetRequirements replaces the certification types an activity requires.
+// An empty list lets anyone be assigned the activity.
+func (s *TrainingService) SetRequirements(ctx context.Context, orgID, userID uuid.UUID, activity types.Activity, request types.RequirementRequest) (*types.Requirement, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !activity.IsValid() {
+		return nil, fmt.Errorf("%w: unknown activity %q", ErrInvalid, activity)
+	}
+	if len(request.CertificationTypeIDs) > MaxRequiredTypes {
+		return nil, fmt.Errorf("%w: an activity requires at most %d certification types", ErrInvalid, MaxRequiredTypes)
+	}
+	return s.repo.SetRequirements(ctx, orgID, &userID, activity, request.CertificationTypeIDs)
+}
+
+// Qualification tells whether an employee holds today every certification
+// the activity requires
+func (s *TrainingService) Qualification(ctx context.Context, orgID, employeeID uuid.UUID, activity types.Activity) (*types.Qualification, error) {
+	if err := s.authService.CheckPermission(ctx, "training:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if !activity.IsValid() {
+		return nil, fmt.Errorf("%w: unknown activity %q", ErrInvalid, activity)
+	}
+
+	missing, err := s.repo.MissingCertifications(ctx, orgID, employeeID, activity, s.today())
+	if err != nil {
+		return nil, err
+	}
+	return &types.Qualification{
+		EmployeeID: employeeID,
+		Activity:   activity,
+		Qualified:  len(missing) == 0,
+		Missing:    missing,
+	}, nil
+}
+
+// RequireEmployee fails with ErrNotQualified unless the employee holds
+// today every certification the activity requires. It is called by other
+// modules when assigning work and checks no permission.
+func (s *TrainingService) RequireEmployee(ctx context.Context, orgID, employeeID uuid.UUID, activity types.Activity) error {
+	missing, err := s.repo.MissingCertifications(ctx, orgID, employeeID, activity, s.today())
+	if err != nil {
+		return err
+	}
+	return notQualified("employee "+employeeID.String(), activity, missing)
+}
+
+// RequireUser is RequireEmployee for the employee record of a user. Users
+// without one only qualify for activities that require no certification.
+func (s *TrainingService) RequireUser(ctx context.Context, orgID, userID uuid.UUID, activity types.Activity) error {
+	employeeID, err := s.repo.EmployeeForUser(ctx, orgID, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	// Without an employee record every required certification is missing
+	missing, err := s.repo.MissingCertifications(ctx, orgID, employeeID, activity, s.today())
+	if err != nil {
+		return err
+	}
+	return notQualified("user "+userID.String(), activity, missing)
+}
+
+// RequireInspector checks that a user may be assigned QC inspections
+func (s *TrainingService) RequireInspector(ctx context.Context, orgID, userID uuid.UUID) error {
+	return s.RequireUser(ctx, orgID, userID, types.ActivityQCInspection)
+}
+
+// RequireHazmatDriver checks that an employee may drive hazmat routes
+func (s *TrainingService) RequireHazmatDriver(ctx context.Context, orgID, employeeID uuid.UUID) error {
+	return s.RequireEmployee(ctx, orgID, employeeID, types.ActivityHazmatRoute)
+}
+
+func notQualified(who string, activity types.Activity, missing []types.CertificationType) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, len(missing))
+	for i, t := range missing {
+		names[i] = t.Name
+	}
+	return fmt.Errorf("%w: %s lacks an active %s certification for %s",
+		ErrNotQualified, who, strings.Join(names, ", "), activity)
+}
+
+// CheckExpiries raises the expiry alerts of the organization's
+// certifications that entered their alert window since the last check
+func (s *TrainingService) CheckExpiries(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	if err := s.authService.CheckPermission(ctx, "training:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.checkExpiries(ctx, orgID)
+}
+
+// CheckAll checks the certifications of every organization. It is called
+// by the scheduler, outside of any user session.
+func (s *TrainingService) CheckAll(ctx context.Context) error {
+	orgIDs, err := s.repo.ListOrganizationIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, orgID := range orgIDs {
+		result, err := s.checkExpiries(ctx, orgID)
+		if err != nil {
+			failed++
+			s.logger.Error("Certification expiry check failed", "organization_id", orgID, "error", err)
+			continue
+		}
+		if len(result.Alerts) > 0 {
+			s.logger.Info("Certifications expiring", "organization_id", orgID, "alerts", len(result.Alerts))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("certification expiry check failed for %d of %d organizations", failed, len(orgIDs))
+	}
+	return nil
+}
+
+func (s *TrainingService) checkExpiries(ctx context.Context, orgID uuid.UUID) (*types.CheckResult, error) {
+	alerts, err := s.repo.ClaimExpiryAlerts(ctx, orgID, s.today(), s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		s.publishEvent(ctx, EventCertificationExpiring, alert)
+	}
+	return &types.CheckResult{Alerts: alerts}, nil
+}
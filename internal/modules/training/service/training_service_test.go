@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/training/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/training/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrainingRepo struct {
+	certTypes    []types.CertificationType
+	certs        []types.Certification
+	requirements map[types.Activity][]uuid.UUID
+	users        map[uuid.UUID]uuid.UUID
+}
+
+func newFakeTrainingRepo() *fakeTrainingRepo {
+	return &fakeTrainingRepo{
+		requirements: make(map[types.Activity][]uuid.UUID),
+		users:        make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+func (f *fakeTrainingRepo) ListOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (f *fakeTrainingRepo) ListTypes(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]types.CertificationType, error) {
+	return f.certTypes, nil
+}
+
+func (f *fakeTrainingRepo) FindType(ctx context.Context, orgID, id uuid.UUID) (*types.CertificationType, error) {
+	for i := range f.certTypes {
+		if f.certTypes[i].ID == id {
+			return &f.certTypes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("certification type %w", repository.ErrNotFound)
+}
+
+func (f *fakeTrainingRepo) CreateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error) {
+	certType.ID = uuid.New()
+	f.certTypes = append(f.certTypes, certType)
+	return &certType, nil
+}
+
+func (f *fakeTrainingRepo) UpdateType(ctx context.Context, userID *uuid.UUID, certType types.CertificationType) (*types.CertificationType, error) {
+	return &certType, nil
+}
+
+func (f *fakeTrainingRepo) ListCertifications(ctx context.Context, filter types.CertificationFilter) ([]types.Certification, error) {
+	return f.certs, nil
+}
+
+func (f *fakeTrainingRepo) FindCertification(ctx context.Context, orgID, id uuid.UUID) (*types.Certification, error) {
+	for i := range f.certs {
+		if f.certs[i].ID == id {
+			return &f.certs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("certification %w", repository.ErrNotFound)
+}
+
+func (f *fakeTrainingRepo) CreateCertification(ctx context.Context, userID *uuid.UUID, cert types.Certification) (*types.Certification, error) {
+	certType, err := f.FindType(ctx, cert.OrganizationID, cert.CertificationTypeID)
+	if err != nil {
+		return nil, err
+	}
+	cert.ID = uuid.New()
+	cert.AlertDays = certType.AlertDays
+	f.certs = append(f.certs, cert)
+	return &cert, nil
+}
+
+func (f *fakeTrainingRepo) RevokeCertification(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, reason string, at time.Time) (*types.Certification, error) {
+	cert, err := f.FindCertification(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	cert.RevokedAt = &at
+	cert.RevokedReason = reason
+	return cert, nil
+}
+
+func (f *fakeTrainingRepo) ListRequirements(ctx context.Context, orgID uuid.UUID) ([]types.Requirement, error) {
+	return nil, nil
+}
+
+func (f *fakeTrainingRepo) SetRequirements(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, activity types.Activity, typeIDs []uuid.UUID) (*types.Requirement, error) {
+	f.requirements[activity] = typeIDs
+	return &types.Requirement{Activity: activity}, nil
+}
+
+// MissingCertifications returns the required types the employee holds no
+// unrevoked, unexpired certification of on the day
+func (f *fakeTrainingRepo) MissingCertifications(ctx context.Context, orgID, employeeID uuid.UUID, activity types.Activity, day time.Time) ([]types.CertificationType, error) {
+	var missing []types.CertificationType
+	for _, typeID := range f.requirements[activity] {
+		held := false
+		for _, c := range f.certs {
+			if c.EmployeeID == employeeID && c.CertificationTypeID == typeID {
+				status := c.StatusOn(day)
+				held = held || status == types.StatusActive || status == types.StatusExpiring
+			}
+		}
+		if !held {
+			certType, _ := f.FindType(ctx, orgID, typeID)
+			missing = append(missing, *certType)
+		}
+	}
+	return missing, nil
+}
+
+func (f *fakeTrainingRepo) EmployeeForUser(ctx context.Context, orgID, userID uuid.UUID) (uuid.UUID, error) {
+	if employeeID, ok := f.users[userID]; ok {
+		return employeeID, nil
+	}
+	return uuid.Nil, fmt.Errorf("employee %w", repository.ErrNotFound)
+}
+
+func (f *fakeTrainingRepo) ClaimExpiryAlerts(ctx context.Context, orgID uuid.UUID, day time.Time, at time.Time) ([]types.ExpiryAlert, error) {
+	return nil, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+func newTestService(repo *fakeTrainingRepo) *TrainingService {
+	svc := NewTrainingService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return time.Date(2025, 6, 15, 9, 30, 0, 0, time.UTC) }
+	return svc
+}
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// hazmatType is a hazmat endorsement valid 24 months, alerted 60 days ahead
+func hazmatType() types.CertificationType {
+	validity := 24
+	return types.CertificationType{
+		ID: uuid.New(), Code: "HAZMAT", Name: "Hazmat endorsement", Category: types.CategoryHazmat,
+		ValidityMonths: &validity, AlertDays: 60, Active: true,
+	}
+}
+
+func TestRecordCertificationExpiresAfterValidity(t *testing.T) {
+	repo := newFakeTrainingRepo()
+	certType := hazmatType()
+	repo.certTypes = append(repo.certTypes, certType)
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	cert, err := svc.RecordCertification(ctx, uuid.New(), uuid.New(), types.CertificationRequest{
+		EmployeeID: uuid.New(), CertificationTypeID: certType.ID, IssuedOn: day(5, 2),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, cert.ExpiresOn)
+	assert.Equal(t, time.Date(2027, 5, 2, 0, 0, 0, 0, time.UTC), *cert.ExpiresOn)
+	assert.Equal(t, types.StatusActive, cert.Status)
+
+	_, err = svc.RecordCertification(ctx, uuid.New(), uuid.New(), types.CertificationRequest{
+		EmployeeID: uuid.New(), CertificationTypeID: certType.ID, IssuedOn: day(6, 16),
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "issued_on in the future")
+
+	_, err = svc.RecordCertification(ctx, uuid.New(), uuid.New(), types.CertificationRequest{
+		EmployeeID: uuid.New(), CertificationTypeID: certType.ID, IssuedOn: day(5, 2), ExpiresOn: ptr(day(5, 1)),
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "expires_on before issued_on")
+}
+
+func TestCertificationStatus(t *testing.T) {
+	today := day(6, 15)
+	cert := types.Certification{AlertDays: 30, IssuedOn: day(1, 1)}
+
+	assert.Equal(t, types.StatusActive, cert.StatusOn(today), "no expiry")
+
+	cert.ExpiresOn = ptr(day(7, 16))
+	assert.Equal(t, types.StatusActive, cert.StatusOn(today))
+	cert.ExpiresOn = ptr(day(7, 15))
+	assert.Equal(t, types.StatusExpiring, cert.StatusOn(today), "expiring on the last day of the window")
+	cert.ExpiresOn = ptr(day(6, 15))
+	assert.Equal(t, types.StatusExpiring, cert.StatusOn(today), "valid through its expiry date")
+	cert.ExpiresOn = ptr(day(6, 14))
+	assert.Equal(t, types.StatusExpired, cert.StatusOn(today))
+
+	revokedAt := day(3, 1)
+	cert.ExpiresOn = nil
+	cert.RevokedAt = &revokedAt
+	assert.Equal(t, types.StatusRevoked, cert.StatusOn(today))
+}
+
+func TestRequireHazmatDriver(t *testing.T) {
+	repo := newFakeTrainingRepo()
+	certType := hazmatType()
+	repo.certTypes = append(repo.certTypes, certType)
+	svc := newTestService(repo)
+	ctx := context.Background()
+	orgID, driverID := uuid.New(), uuid.New()
+
+	require.NoError(t, svc.RequireHazmatDriver(ctx, orgID, driverID), "no requirement set")
+
+	_, err := svc.SetRequirements(ctx, orgID, uuid.New(), types.ActivityHazmatRoute,
+		types.RequirementRequest{CertificationTypeIDs: []uuid.UUID{certType.ID}})
+	require.NoError(t, err)
+
+	err = svc.RequireHazmatDriver(ctx, orgID, driverID)
+	assert.ErrorIs(t, err, ErrNotQualified)
+	assert.Contains(t, err.Error(), "Hazmat endorsement")
+
+	// An expired endorsement does not qualify, a renewed one does
+	repo.certs = append(repo.certs, types.Certification{ID: uuid.New(), EmployeeID: driverID,
+		CertificationTypeID: certType.ID, IssuedOn: day(1, 1).AddDate(-2, 0, 0), ExpiresOn: ptr(day(1, 1))})
+	assert.ErrorIs(t, svc.RequireHazmatDriver(ctx, orgID, driverID), ErrNotQualified)
+
+	cert, err := svc.RecordCertification(ctx, orgID, uuid.New(), types.CertificationRequest{
+		EmployeeID: driverID, CertificationTypeID: certType.ID, IssuedOn: day(6, 1),
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.RequireHazmatDriver(ctx, orgID, driverID))
+
+	qualification, err := svc.Qualification(ctx, orgID, driverID, types.ActivityHazmatRoute)
+	require.NoError(t, err)
+	assert.True(t, qualification.Qualified)
+
+	// Revoking stops qualifying the driver straight away
+	_, err = svc.RevokeCertification(ctx, orgID, uuid.New(), cert.ID, types.RevokeRequest{})
+	assert.ErrorIs(t, err, ErrInvalid, "reason required")
+	_, err = svc.RevokeCertification(ctx, orgID, uuid.New(), cert.ID, types.RevokeRequest{Reason: "Failed medical"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.RequireHazmatDriver(ctx, orgID, driverID), ErrNotQualified)
+}
+
+func TestRequireInspector(t *testing.T) {
+	repo := newFakeTrainingRepo()
+	inspectorType := types.CertificationType{ID: uuid.New(), Code: "QC-L2", Name: "QC inspector level 2",
+		Category: types.CategoryInspector, AlertDays: 30, Active: true}
+	repo.certTypes = append(repo.certTypes, inspectorType)
+	repo.requirements[types.ActivityQCInspection] = []uuid.UUID{inspectorType.ID}
+	svc := newTestService(repo)
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	// A user without an employee record holds no certification
+	err := svc.RequireInspector(ctx, orgID, uuid.New())
+	assert.ErrorIs(t, err, ErrNotQualified)
+
+	userID, employeeID := uuid.New(), uuid.New()
+	repo.users[userID] = employeeID
+	assert.ErrorIs(t, svc.RequireInspector(ctx, orgID, userID), ErrNotQualified)
+
+	repo.certs = append(repo.certs, types.Certification{ID: uuid.New(), EmployeeID: employeeID,
+		CertificationTypeID: inspectorType.ID, IssuedOn: day(2, 1)})
+	assert.NoError(t, svc.RequireInspector(ctx, orgID, userID))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,198 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category groups certification types
+type Category string
+
+const (
+	CategoryForklift  Category = "forklift"
+	CategoryHazmat    Category = "hazmat"
+	CategoryInspector Category = "inspector"
+	CategorySafety    Category = "safety"
+	CategoryOther     Category = "other"
+)
+
+// IsValid reports whether the category is known
+func (c Category) IsValid() bool {
+	switch c {
+	case CategoryForklift, CategoryHazmat, CategoryInspector, CategorySafety, CategoryOther:
+		return true
+	}
+	return false
+}
+
+// Activity is work that can only be assigned to certified employees
+type Activity string
+
+const (
+	// ActivityQCInspection is inspecting goods for quality control
+	ActivityQCInspection Activity = "qc_inspection"
+	// ActivityHazmatRoute is driving a delivery route carrying dangerous goods
+	ActivityHazmatRoute Activity = "hazmat_route"
+)
+
+// IsValid reports whether the activity is known
+func (a Activity) IsValid() bool {
+	return a == ActivityQCInspection || a == ActivityHazmatRoute
+}
+
+// CertificationType is a qualification employees can hold, such as a
+// forklift licence or a hazmat endorsement
+type CertificationType struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Code           string    `json:"code"`
+	Name           string    `json:"name"`
+	Category       Category  `json:"category"`
+	// ValidityMonths sets the expiry of new certifications; nil types
+	// do not expire
+	ValidityMonths *int `json:"validity_months,omitempty"`
+	// AlertDays is how many days before expiry the holder is alerted
+	AlertDays int       `json:"alert_days"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CertificationTypeRequest creates or replaces a certification type
+type CertificationTypeRequest struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Category       Category `json:"category"`
+	ValidityMonths *int     `json:"validity_months,omitempty"`
+	AlertDays      *int     `json:"alert_days,omitempty"`
+	Active         *bool    `json:"active,omitempty"`
+}
+
+// CertificationStatus is where a certification stands on a given day
+type CertificationStatus string
+
+const (
+	StatusActive   CertificationStatus = "active"
+	StatusExpiring CertificationStatus = "expiring"
+	StatusExpired  CertificationStatus = "expired"
+	StatusRevoked  CertificationStatus = "revoked"
+)
+
+// IsValid reports whether the status is known
+func (s CertificationStatus) IsValid() bool {
+	switch s {
+	case StatusActive, StatusExpiring, StatusExpired, StatusRevoked:
+		return true
+	}
+	return false
+}
+
+// Certification is a certification held by an employee
+type Certification struct {
+	ID                  uuid.UUID `json:"id"`
+	OrganizationID      uuid.UUID `json:"organization_id"`
+	EmployeeID          uuid.UUID `json:"employee_id"`
+	EmployeeName        string    `json:"employee_name"`
+	CertificationTypeID uuid.UUID `json:"certification_type_id"`
+	TypeCode            string    `json:"type_code"`
+	TypeName            string    `json:"type_name"`
+	Category            Category  `json:"category"`
+	// AlertDays is the alert window of the certification's type
+	AlertDays         int                 `json:"alert_days"`
+	CertificateNumber string              `json:"certificate_number,omitempty"`
+	Issuer            string              `json:"issuer,omitempty"`
+	IssuedOn          time.Time           `json:"issued_on"`
+	ExpiresOn         *time.Time          `json:"expires_on,omitempty"`
+	Status            CertificationStatus `json:"status"`
+	Notes             string              `json:"notes,omitempty"`
+	RevokedAt         *time.Time          `json:"revoked_at,omitempty"`
+	RevokedReason     string              `json:"revoked_reason,omitempty"`
+	AlertedAt         *time.Time          `json:"alerted_at,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
+	CreatedBy         *uuid.UUID          `json:"created_by,omitempty"`
+}
+
+// StatusOn reports the status of the certification on the day
+func (c Certification) StatusOn(day time.Time) CertificationStatus {
+	switch {
+	case c.RevokedAt != nil:
+		return StatusRevoked
+	case c.ExpiresOn == nil:
+		return StatusActive
+	case c.ExpiresOn.Before(day):
+		return StatusExpired
+	case !c.ExpiresOn.After(day.AddDate(0, 0, c.AlertDays)):
+		return StatusExpiring
+	}
+	return StatusActive
+}
+
+// CertificationRequest records a certification of an employee. Without an
+// expiry date, it expires after the validity of its type.
+type CertificationRequest struct {
+	EmployeeID          uuid.UUID  `json:"employee_id"`
+	CertificationTypeID uuid.UUID  `json:"certification_type_id"`
+	CertificateNumber   string     `json:"certificate_number"`
+	Issuer              string     `json:"issuer"`
+	IssuedOn            time.Time  `json:"issued_on"`
+	ExpiresOn           *time.Time `json:"expires_on,omitempty"`
+	Notes               string     `json:"notes"`
+}
+
+// RevokeRequest revokes a certification before it expires
+type RevokeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CertificationFilter selects certifications
+type CertificationFilter struct {
+	OrganizationID      uuid.UUID
+	EmployeeID          *uuid.UUID
+	CertificationTypeID *uuid.UUID
+	Category            Category
+	// Status selects certifications by their status today
+	Status CertificationStatus
+	// ExpiringBefore selects unrevoked certifications expiring on or
+	// before the day
+	ExpiringBefore *time.Time
+}
+
+// Requirement lists the certification types an activity requires
+type Requirement struct {
+	Activity           Activity            `json:"activity"`
+	CertificationTypes []CertificationType `json:"certification_types"`
+}
+
+// RequirementRequest replaces the certification types an activity requires
+type RequirementRequest struct {
+	CertificationTypeIDs []uuid.UUID `json:"certification_type_ids"`
+}
+
+// Qualification tells whether an employee may be assigned an activity
+type Qualification struct {
+	EmployeeID uuid.UUID `json:"employee_id"`
+	Activity   Activity  `json:"activity"`
+	Qualified  bool      `json:"qualified"`
+	// Missing are the required certification types the employee holds no
+	// active certification of
+	Missing []CertificationType `json:"missing"`
+}
+
+// ExpiryAlert is raised once for a certification entering its alert window
+type ExpiryAlert struct {
+	CertificationID     uuid.UUID  `json:"certification_id"`
+	OrganizationID      uuid.UUID  `json:"organization_id"`
+	EmployeeID          uuid.UUID  `json:"employee_id"`
+	EmployeeName        string     `json:"employee_name"`
+	EmployeeUserID      *uuid.UUID `json:"employee_user_id,omitempty"`
+	ManagerUserID       *uuid.UUID `json:"manager_user_id,omitempty"`
+	CertificationTypeID uuid.UUID  `json:"certification_type_id"`
+	TypeName            string     `json:"type_name"`
+	ExpiresOn           time.Time  `json:"expires_on"`
+}
+
+// CheckResult is the outcome of an expiry check
+type CheckResult struct {
+	Alerts []ExpiryAlert `json:"alerts"`
+}
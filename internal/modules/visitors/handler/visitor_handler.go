@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// VisitorHandler handles the visitor log
+type VisitorHandler struct {
+	service *service.VisitorService
+}
+
+func NewVisitorHandler(service *service.VisitorService) *VisitorHandler {
+	return &VisitorHandler{service: service}
+}
+
+func (h *VisitorHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/visits", h.ListVisits)
+	router.POST("/api/visits", h.RegisterVisit)
+	router.GET("/api/visits/:id", h.GetVisit)
+	router.PUT("/api/visits/:id", h.UpdateVisit)
+	router.POST("/api/visits/:id/check-in", h.CheckIn)
+	router.POST("/api/visits/:id/check-out", h.CheckOut)
+	router.POST("/api/visits/:id/cancel", h.CancelVisit)
+
+	router.GET("/api/visitors-on-site", h.ListOnSite)
+}
+
+// ListVisits handles GET /api/visits, filtered by status, warehouse_id,
+// host_employee_id, and from and to as RFC 3339 times
+func (h *VisitorHandler) ListVisits(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.VisitFilter{
+		OrganizationID: authCtx.OrganizationID,
+		Status:         types.VisitStatus(q.Get("status")),
+	}
+	var err error
+	if filter.WarehouseID, err = parseOptionalUUID(q.Get("warehouse_id")); err != nil {
+		http.Error(w, "Invalid warehouse_id", http.StatusBadRequest)
+		return
+	}
+	if filter.HostEmployeeID, err = parseOptionalUUID(q.Get("host_employee_id")); err != nil {
+		http.Error(w, "Invalid host_employee_id", http.StatusBadRequest)
+		return
+	}
+	if filter.From, err = parseOptionalTime(q.Get("from")); err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseOptionalTime(q.Get("to")); err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	visits, err := h.service.ListVisits(r.Context(), filter)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visits)
+}
+
+// ListOnSite handles GET /api/visitors-on-site, listing the visitors checked
+// in, optionally at one warehouse_id
+func (h *VisitorHandler) ListOnSite(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	warehouseID, err := parseOptionalUUID(r.URL.Query().Get("warehouse_id"))
+	if err != nil {
+		http.Error(w, "Invalid warehouse_id", http.StatusBadRequest)
+		return
+	}
+
+	visits, err := h.service.ListOnSite(r.Context(), authCtx.OrganizationID, warehouseID)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visits)
+}
+
+// RegisterVisit handles POST /api/visits
+func (h *VisitorHandler) RegisterVisit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.VisitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.RegisterVisit(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, visit)
+}
+
+// GetVisit handles GET /api/visits/:id
+func (h *VisitorHandler) GetVisit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.GetVisit(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visit)
+}
+
+// UpdateVisit handles PUT /api/visits/:id
+func (h *VisitorHandler) UpdateVisit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.VisitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.UpdateVisit(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visit)
+}
+
+// CheckIn handles POST /api/visits/:id/check-in
+func (h *VisitorHandler) CheckIn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.CheckIn(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visit)
+}
+
+// CheckOut handles POST /api/visits/:id/check-out
+func (h *VisitorHandler) CheckOut(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.CheckOutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.CheckOut(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visit)
+}
+
+// CancelVisit handles POST /api/visits/:id/cancel
+func (h *VisitorHandler) CancelVisit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+
+	visit, err := h.service.CancelVisit(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeVisitorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, visit)
+}
+
+func parseOptionalUUID(v string) (*uuid.UUID, error) {
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func parseOptionalTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeVisitorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrDuplicate), errors.Is(err, repository.ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
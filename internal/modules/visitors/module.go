@@ -0,0 +1,84 @@
+package visitors
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/service"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/julienschmidt/httprouter"
+)
+
+// VisitorsModule represents the visitor log module
+type VisitorsModule struct {
+	visitorService *service.VisitorService
+	visitorHandler *handler.VisitorHandler
+	logger         *slog.Logger
+}
+
+// NewVisitorsModule creates a new visitors module
+func NewVisitorsModule() *VisitorsModule {
+	return &VisitorsModule{}
+}
+
+// Name returns the module name
+func (m *VisitorsModule) Name() string {
+	return "visitors"
+}
+
+// Init initializes the visitors module
+func (m *VisitorsModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "visitors")
+	m.logger.Info("Initializing visitors module")
+
+	// Create repositories
+	visitorRepo := repository.NewVisitorRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.visitorService = service.NewVisitorService(visitorRepo, authAdapter, deps.EventBus, m.logger)
+
+	// Create handlers
+	m.visitorHandler = handler.NewVisitorHandler(m.visitorService)
+
+	m.logger.Info("Visitors module initialized successfully")
+	return nil
+}
+
+// VisitorService returns the visitor service, which also loads visits for
+// printing visitor badges
+func (m *VisitorsModule) VisitorService() *service.VisitorService {
+	return m.visitorService
+}
+
+// SetPushNotifier sets the notifier that tells hosts their visitor has
+// arrived. It must be called after Init.
+func (m *VisitorsModule) SetPushNotifier(notifier push.Notifier) {
+	if m.visitorService != nil {
+		m.visitorService.SetPushNotifier(notifier)
+	}
+}
+
+// RegisterRoutes registers visitors module routes
+func (m *VisitorsModule) RegisterRoutes(router interface{}) {
+	if m.visitorHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.visitorHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers registers event handlers for the visitors module
+func (m *VisitorsModule) RegisterEventHandlers(bus interface{}) {
+	// The visitors module only publishes events
+}
+
+// Health checks the health of the visitors module
+func (m *VisitorsModule) Health() error {
+	return nil
+}
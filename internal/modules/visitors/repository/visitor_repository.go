@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a visit, host or warehouse does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a badge is already issued to a visitor
+	// on site
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidState is returned when a visit's status does not allow the
+	// change, such as checking out a visitor who never checked in
+	ErrInvalidState = errors.New("state does not allow this")
+)
+
+// VisitorRepo defines the interface for visitor repository operations
+type VisitorRepo interface {
+	ListVisits(ctx context.Context, filter types.VisitFilter) ([]types.Visit, error)
+	FindVisit(ctx context.Context, orgID, id uuid.UUID) (*types.Visit, error)
+	FindHost(ctx context.Context, orgID, employeeID uuid.UUID) (*types.Host, error)
+	WarehouseExists(ctx context.Context, orgID, warehouseID uuid.UUID) (bool, error)
+	CreateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error)
+	UpdateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error)
+	CheckIn(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckInRequest, at time.Time) (*types.Visit, error)
+	CheckOut(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckOutRequest, at time.Time) (*types.Visit, error)
+	Cancel(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, at time.Time) (*types.Visit, error)
+}
+
+// VisitorRepository stores the visitor log
+type VisitorRepository struct {
+	db *sql.DB
+}
+
+// Ensure VisitorRepository implements VisitorRepo interface
+var _ VisitorRepo = &VisitorRepository{}
+
+func NewVisitorRepository(db *sql.DB) *VisitorRepository {
+	return &VisitorRepository{db: db}
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+const visitColumns = `v.id, v.organization_id, v.visitor_name, COALESCE(v.visitor_company, ''),
+	COALESCE(v.visitor_email, ''), COALESCE(v.visitor_phone, ''), v.host_employee_id, e.name, v.warehouse_id,
+	COALESCE(w.name, ''), COALESCE(v.purpose, ''), COALESCE(v.vehicle_plate, ''), v.expected_at, v.status,
+	COALESCE(v.badge_number, ''), COALESCE(v.id_document, ''), v.safety_briefed, v.checked_in_at, v.checked_in_by,
+	v.checked_out_at, v.checked_out_by, v.badge_returned, v.cancelled_at, v.cancelled_by, COALESCE(v.notes, ''),
+	v.created_at, v.updated_at, v.created_by`
+
+const visitFrom = `visits v
+	JOIN employees e ON e.id = v.host_employee_id
+	LEFT JOIN warehouses w ON w.id = v.warehouse_id`
+
+func scanVisit(row interface{ Scan(...interface{}) error }) (*types.Visit, error) {
+	var v types.Visit
+	var warehouseID, checkedInBy, checkedOutBy, cancelledBy, createdBy uuid.NullUUID
+	var checkedInAt, checkedOutAt, cancelledAt sql.NullTime
+	var badgeReturned sql.NullBool
+	if err := row.Scan(&v.ID, &v.OrganizationID, &v.VisitorName, &v.VisitorCompany, &v.VisitorEmail,
+		&v.VisitorPhone, &v.HostEmployeeID, &v.HostName, &warehouseID, &v.WarehouseName, &v.Purpose,
+		&v.VehiclePlate, &v.ExpectedAt, &v.Status, &v.BadgeNumber, &v.IDDocument, &v.SafetyBriefed,
+		&checkedInAt, &checkedInBy, &checkedOutAt, &checkedOutBy, &badgeReturned, &cancelledAt, &cancelledBy,
+		&v.Notes, &v.CreatedAt, &v.UpdatedAt, &createdBy); err != nil {
+		return nil, err
+	}
+	v.WarehouseID = nullUUID(warehouseID)
+	v.CheckedInAt = nullTime(checkedInAt)
+	v.CheckedInBy = nullUUID(checkedInBy)
+	v.CheckedOutAt = nullTime(checkedOutAt)
+	v.CheckedOutBy = nullUUID(checkedOutBy)
+	v.CancelledAt = nullTime(cancelledAt)
+	v.CancelledBy = nullUUID(cancelledBy)
+	v.CreatedBy = nullUUID(createdBy)
+	if badgeReturned.Valid {
+		v.BadgeReturned = &badgeReturned.Bool
+	}
+	return &v, nil
+}
+
+// ListVisits returns the visits matching the filter, latest first
+func (r *VisitorRepository) ListVisits(ctx context.Context, filter types.VisitFilter) ([]types.Visit, error) {
+	where := []string{"v.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		where = append(where, fmt.Sprintf("v.status = $%d", len(args)))
+	}
+	if filter.WarehouseID != nil {
+		args = append(args, *filter.WarehouseID)
+		where = append(where, fmt.Sprintf("v.warehouse_id = $%d", len(args)))
+	}
+	if filter.HostEmployeeID != nil {
+		args = append(args, *filter.HostEmployeeID)
+		where = append(where, fmt.Sprintf("v.host_employee_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where = append(where, fmt.Sprintf("COALESCE(v.checked_in_at, v.expected_at) >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where = append(where, fmt.Sprintf("COALESCE(v.checked_in_at, v.expected_at) < $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+visitColumns+` FROM `+visitFrom+`
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY COALESCE(v.checked_in_at, v.expected_at) DESC, v.id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list visits: %w", err)
+	}
+	defer rows.Close()
+
+	visits := []types.Visit{}
+	for rows.Next() {
+		v, err := scanVisit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan visit: %w", err)
+		}
+		visits = append(visits, *v)
+	}
+	return visits, rows.Err()
+}
+
+func (r *VisitorRepository) FindVisit(ctx context.Context, orgID, id uuid.UUID) (*types.Visit, error) {
+	v, err := scanVisit(r.db.QueryRowContext(ctx, `
+		SELECT `+visitColumns+` FROM `+visitFrom+` WHERE v.organization_id = $1 AND v.id = $2
+	`, orgID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("visit %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find visit: %w", err)
+	}
+	return v, nil
+}
+
+// FindHost returns an active employee of the organization who can host
+// visitors
+func (r *VisitorRepository) FindHost(ctx context.Context, orgID, employeeID uuid.UUID) (*types.Host, error) {
+	var host types.Host
+	var userID uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, user_id FROM employees
+		WHERE organization_id = $1 AND id = $2 AND active = true AND deleted_at IS NULL
+	`, orgID, employeeID).Scan(&host.EmployeeID, &host.Name, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("host employee %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find host: %w", err)
+	}
+	host.UserID = nullUUID(userID)
+	return &host, nil
+}
+
+func (r *VisitorRepository) WarehouseExists(ctx context.Context, orgID, warehouseID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM warehouses WHERE organization_id = $1 AND id = $2)
+	`, orgID, warehouseID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to find warehouse: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *VisitorRepository) CreateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO visits (
+			organization_id, visitor_name, visitor_company, visitor_email, visitor_phone, host_employee_id,
+			warehouse_id, purpose, vehicle_plate, expected_at, notes, created_by, updated_by
+		) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''),
+			$10, NULLIF($11, ''), $12, $12)
+		RETURNING id`,
+		visit.OrganizationID, visit.VisitorName, visit.VisitorCompany, visit.VisitorEmail, visit.VisitorPhone,
+		visit.HostEmployeeID, visit.WarehouseID, visit.Purpose, visit.VehiclePlate, visit.ExpectedAt, visit.Notes,
+		userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create visit: %w", err)
+	}
+	return r.FindVisit(ctx, visit.OrganizationID, id)
+}
+
+// UpdateVisit replaces the details of a visit that is still expected
+func (r *VisitorRepository) UpdateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error) {
+	return r.transition(ctx, visit.OrganizationID, visit.ID, `
+		UPDATE visits SET
+			visitor_name = $3, visitor_company = NULLIF($4, ''), visitor_email = NULLIF($5, ''),
+			visitor_phone = NULLIF($6, ''), host_employee_id = $7, warehouse_id = $8, purpose = NULLIF($9, ''),
+			vehicle_plate = NULLIF($10, ''), expected_at = $11, notes = NULLIF($12, ''),
+			updated_at = now(), updated_by = $13
+		WHERE organization_id = $1 AND id = $2 AND status = 'expected'`,
+		visit.OrganizationID, visit.ID, visit.VisitorName, visit.VisitorCompany, visit.VisitorEmail,
+		visit.VisitorPhone, visit.HostEmployeeID, visit.WarehouseID, visit.Purpose, visit.VehiclePlate,
+		visit.ExpectedAt, visit.Notes, userID)
+}
+
+// CheckIn checks in an expected visitor, issuing the badge
+func (r *VisitorRepository) CheckIn(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckInRequest, at time.Time) (*types.Visit, error) {
+	visit, err := r.transition(ctx, orgID, id, `
+		UPDATE visits SET
+			status = 'checked_in', badge_number = NULLIF($3, ''), id_document = NULLIF($4, ''),
+			safety_briefed = $5, checked_in_at = $6, checked_in_by = $7, updated_at = now(), updated_by = $7
+		WHERE organization_id = $1 AND id = $2 AND status = 'expected'`,
+		orgID, id, req.BadgeNumber, req.IDDocument, req.SafetyBriefed, at, userID)
+	if isUniqueViolation(err, "visits_badge_on_site_key") {
+		return nil, fmt.Errorf("badge %s is issued to a visitor on site: %w", req.BadgeNumber, ErrDuplicate)
+	}
+	return visit, err
+}
+
+// CheckOut checks out a visitor on site
+func (r *VisitorRepository) CheckOut(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckOutRequest, at time.Time) (*types.Visit, error) {
+	return r.transition(ctx, orgID, id, `
+		UPDATE visits SET
+			status = 'checked_out', badge_returned = CASE WHEN badge_number IS NULL THEN NULL ELSE $3 END,
+			checked_out_at = GREATEST($4, checked_in_at), checked_out_by = $5, updated_at = now(), updated_by = $5
+		WHERE organization_id = $1 AND id = $2 AND status = 'checked_in'`,
+		orgID, id, req.BadgeReturned, at, userID)
+}
+
+// Cancel cancels a visit that is still expected
+func (r *VisitorRepository) Cancel(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, at time.Time) (*types.Visit, error) {
+	return r.transition(ctx, orgID, id, `
+		UPDATE visits SET
+			status = 'cancelled', cancelled_at = $3, cancelled_by = $4, updated_at = now(), updated_by = $4
+		WHERE organization_id = $1 AND id = $2 AND status = 'expected'`,
+		orgID, id, at, userID)
+}
+
+// transition runs an update of a visit guarded by its status, the first two
+// arguments being its organization and ID, and returns the updated visit.
+// It fails with ErrInvalidState when the visit is not in the status the
+// update requires.
+func (r *VisitorRepository) transition(ctx context.Context, orgID, id uuid.UUID, query string, args ...interface{}) (*types.Visit, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update visit: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update visit: %w", err)
+	}
+	visit, err := r.FindVisit(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("visit is %s: %w", visit.Status, ErrInvalidState)
+	}
+	return visit, nil
+}
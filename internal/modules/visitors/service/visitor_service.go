@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/types"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultPageSize is the number of visits listed when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize bounds the number of visits listed at once
+	MaxPageSize = 500
+	// pushTimeout bounds the arrival notification, which is sent after the
+	// request returns
+	pushTimeout = 15 * time.Second
+	// EventVisitorRegistered is published when a visit is registered
+	EventVisitorRegistered = "visitor.registered"
+	// EventVisitorCheckedIn is published when a visitor arrives on site
+	EventVisitorCheckedIn = "visitor.checked_in"
+	// EventVisitorCheckedOut is published when a visitor leaves the site
+	EventVisitorCheckedOut = "visitor.checked_out"
+	// EventVisitorCancelled is published when an expected visit is cancelled
+	EventVisitorCancelled = "visitor.cancelled"
+)
+
+// ErrInvalid wraps validation failures of visits
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the visitor service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// VisitorService keeps the visitor log of offices and warehouses: who was
+// expected, who they came to see, and when they were on site
+type VisitorService struct {
+	repo        repository.VisitorRepo
+	authService AuthService
+	eventBus    *events.Bus
+	notifier    push.Notifier
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewVisitorService(repo repository.VisitorRepo, authService AuthService, eventBus *events.Bus, logger *slog.Logger) *VisitorService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &VisitorService{
+		repo:        repo,
+		authService: authService,
+		eventBus:    eventBus,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// SetPushNotifier sets the notifier that tells hosts their visitor has
+// arrived. Without one hosts only learn of it through the visitor.checked_in
+// event.
+func (s *VisitorService) SetPushNotifier(notifier push.Notifier) {
+	s.notifier = notifier
+}
+
+func (s *VisitorService) publishEvent(ctx context.Context, eventType string, payload interface{}) {
+	if s.eventBus != nil {
+		if err := s.eventBus.Publish(ctx, eventType, payload); err != nil {
+			s.logger.Warn("Failed to publish visitor event", "event", eventType, "error", err)
+		}
+	}
+}
+
+// ListVisits returns the visits matching the filter, latest first
+func (s *VisitorService) ListVisits(ctx context.Context, filter types.VisitFilter) ([]types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalid)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListVisits(ctx, filter)
+}
+
+// ListOnSite returns the visitors checked in at the warehouse, or anywhere
+// when warehouseID is nil, e.g. for an evacuation roll call
+func (s *VisitorService) ListOnSite(ctx context.Context, orgID uuid.UUID, warehouseID *uuid.UUID) ([]types.Visit, error) {
+	return s.ListVisits(ctx, types.VisitFilter{
+		OrganizationID: orgID,
+		Status:         types.VisitCheckedIn,
+		WarehouseID:    warehouseID,
+		Limit:          MaxPageSize,
+	})
+}
+
+// GetVisit returns a visit
+func (s *VisitorService) GetVisit(ctx context.Context, orgID, id uuid.UUID) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindVisit(ctx, orgID, id)
+}
+
+// validateVisit checks a visit request, including that its host and
+// warehouse belong to the organization
+func (s *VisitorService) validateVisit(ctx context.Context, orgID uuid.UUID, request types.VisitRequest) (types.Visit, error) {
+	visit := types.Visit{
+		OrganizationID: orgID,
+		VisitorName:    strings.TrimSpace(request.VisitorName),
+		VisitorCompany: strings.TrimSpace(request.VisitorCompany),
+		VisitorEmail:   strings.TrimSpace(request.VisitorEmail),
+		VisitorPhone:   strings.TrimSpace(request.VisitorPhone),
+		HostEmployeeID: request.HostEmployeeID,
+		WarehouseID:    request.WarehouseID,
+		Purpose:        strings.TrimSpace(request.Purpose),
+		VehiclePlate:   strings.ToUpper(strings.TrimSpace(request.VehiclePlate)),
+		Notes:          strings.TrimSpace(request.Notes),
+	}
+	if visit.VisitorName == "" {
+		return visit, fmt.Errorf("%w: visitor_name is required", ErrInvalid)
+	}
+	if visit.HostEmployeeID == uuid.Nil {
+		return visit, fmt.Errorf("%w: host_employee_id is required", ErrInvalid)
+	}
+	if request.ExpectedAt != nil {
+		visit.ExpectedAt = *request.ExpectedAt
+	} else {
+		visit.ExpectedAt = s.now()
+	}
+
+	if _, err := s.repo.FindHost(ctx, orgID, visit.HostEmployeeID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return visit, fmt.Errorf("%w: host is not an active employee", ErrInvalid)
+		}
+		return visit, err
+	}
+	if visit.WarehouseID != nil {
+		exists, err := s.repo.WarehouseExists(ctx, orgID, *visit.WarehouseID)
+		if err != nil {
+			return visit, err
+		}
+		if !exists {
+			return visit, fmt.Errorf("%w: unknown warehouse", ErrInvalid)
+		}
+	}
+	return visit, nil
+}
+
+// RegisterVisit pre-registers a visit, or registers a walk-in expected now
+func (s *VisitorService) RegisterVisit(ctx context.Context, orgID, userID uuid.UUID, request types.VisitRequest) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	visit, err := s.validateVisit(ctx, orgID, request)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateVisit(ctx, &userID, visit)
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventVisitorRegistered, created)
+	return created, nil
+}
+
+// UpdateVisit replaces the details of a visit that is still expected
+func (s *VisitorService) UpdateVisit(ctx context.Context, orgID, userID, id uuid.UUID, request types.VisitRequest) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	visit, err := s.validateVisit(ctx, orgID, request)
+	if err != nil {
+		return nil, err
+	}
+	visit.ID = id
+	return s.repo.UpdateVisit(ctx, &userID, visit)
+}
+
+// CheckIn checks in an expected visitor once they have had the site safety
+// briefing, and lets their host know they have arrived
+func (s *VisitorService) CheckIn(ctx context.Context, orgID, userID, id uuid.UUID, request types.CheckInRequest) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	request.BadgeNumber = strings.TrimSpace(request.BadgeNumber)
+	request.IDDocument = strings.TrimSpace(request.IDDocument)
+	if !request.SafetyBriefed {
+		return nil, fmt.Errorf("%w: visitors must be given the safety briefing before check-in", ErrInvalid)
+	}
+
+	visit, err := s.repo.CheckIn(ctx, orgID, id, &userID, request, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventVisitorCheckedIn, visit)
+	s.notifyHost(ctx, *visit)
+	return visit, nil
+}
+
+// notifyHost pushes the visitor's arrival to their host in the background;
+// the check-in is already recorded, so failures are only logged
+func (s *VisitorService) notifyHost(ctx context.Context, visit types.Visit) {
+	if s.notifier == nil {
+		return
+	}
+	host, err := s.repo.FindHost(ctx, visit.OrganizationID, visit.HostEmployeeID)
+	if err != nil {
+		s.logger.Warn("Failed to find visit host", "error", err, "visit_id", visit.ID)
+		return
+	}
+	if host.UserID == nil {
+		return
+	}
+
+	body := visit.VisitorName
+	if visit.VisitorCompany != "" {
+		body += " (" + visit.VisitorCompany + ")"
+	}
+	body += " is at reception"
+	if visit.WarehouseName != "" {
+		body += " at " + visit.WarehouseName
+	}
+
+	notification := push.Notification{
+		OrganizationID: visit.OrganizationID,
+		UserIDs:        []uuid.UUID{*host.UserID},
+		Title:          "Your visitor has arrived",
+		Body:           body,
+		Data: map[string]string{
+			"type":     "visitor_arrived",
+			"visit_id": visit.ID.String(),
+		},
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		defer cancel()
+		if err := s.notifier.Send(ctx, notification); err != nil {
+			s.logger.Warn("Failed to push visitor arrival", "error", err, "visit_id", visit.ID)
+		}
+	}()
+}
+
+// CheckOut checks out a visitor on site, recording whether their badge was
+// handed back
+func (s *VisitorService) CheckOut(ctx context.Context, orgID, userID, id uuid.UUID, request types.CheckOutRequest) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	visit, err := s.repo.CheckOut(ctx, orgID, id, &userID, request, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventVisitorCheckedOut, visit)
+	return visit, nil
+}
+
+// CancelVisit cancels a visit that is still expected. Visits are kept for
+// the audit trail rather than deleted.
+func (s *VisitorService) CancelVisit(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Visit, error) {
+	if err := s.authService.CheckPermission(ctx, "visitors:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	visit, err := s.repo.Cancel(ctx, orgID, id, &userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.publishEvent(ctx, EventVisitorCancelled, visit)
+	return visit, nil
+}
+
+// Load returns a visit as template data for printing its visitor badge.
+// Badges are only printed for visits that are expected or on site.
+func (s *VisitorService) Load(ctx context.Context, orgID, recordID uuid.UUID) (map[string]interface{}, error) {
+	visit, err := s.GetVisit(ctx, orgID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if visit.Status != types.VisitExpected && visit.Status != types.VisitCheckedIn {
+		return nil, fmt.Errorf("%w: no badge for a visit that is %s", ErrInvalid, visit.Status)
+	}
+
+	raw, err := json.Marshal(visit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode visit: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode visit: %w", err)
+	}
+	return data, nil
+}
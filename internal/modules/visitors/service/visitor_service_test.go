@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/visitors/types"
+	"github.com/KevTiv/alieze-erp/pkg/push"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVisitorRepo struct {
+	visits []types.Visit
+	hosts  []types.Host
+}
+
+func (f *fakeVisitorRepo) ListVisits(ctx context.Context, filter types.VisitFilter) ([]types.Visit, error) {
+	return f.visits, nil
+}
+
+func (f *fakeVisitorRepo) FindVisit(ctx context.Context, orgID, id uuid.UUID) (*types.Visit, error) {
+	for i := range f.visits {
+		if f.visits[i].ID == id {
+			return &f.visits[i], nil
+		}
+	}
+	return nil, fmt.Errorf("visit %w", repository.ErrNotFound)
+}
+
+func (f *fakeVisitorRepo) FindHost(ctx context.Context, orgID, employeeID uuid.UUID) (*types.Host, error) {
+	for i := range f.hosts {
+		if f.hosts[i].EmployeeID == employeeID {
+			return &f.hosts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("host employee %w", repository.ErrNotFound)
+}
+
+func (f *fakeVisitorRepo) WarehouseExists(ctx context.Context, orgID, warehouseID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeVisitorRepo) CreateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error) {
+	visit.ID = uuid.New()
+	visit.Status = types.VisitExpected
+	f.visits = append(f.visits, visit)
+	return &visit, nil
+}
+
+func (f *fakeVisitorRepo) UpdateVisit(ctx context.Context, userID *uuid.UUID, visit types.Visit) (*types.Visit, error) {
+	return f.transition(visit.ID, types.VisitExpected, func(v *types.Visit) {
+		visit.Status = v.Status
+		*v = visit
+	})
+}
+
+func (f *fakeVisitorRepo) CheckIn(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckInRequest, at time.Time) (*types.Visit, error) {
+	return f.transition(id, types.VisitExpected, func(v *types.Visit) {
+		v.Status = types.VisitCheckedIn
+		v.BadgeNumber = req.BadgeNumber
+		v.SafetyBriefed = req.SafetyBriefed
+		v.CheckedInAt = &at
+	})
+}
+
+func (f *fakeVisitorRepo) CheckOut(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, req types.CheckOutRequest, at time.Time) (*types.Visit, error) {
+	return f.transition(id, types.VisitCheckedIn, func(v *types.Visit) {
+		v.Status = types.VisitCheckedOut
+		v.BadgeReturned = &req.BadgeReturned
+		v.CheckedOutAt = &at
+	})
+}
+
+func (f *fakeVisitorRepo) Cancel(ctx context.Context, orgID, id uuid.UUID, userID *uuid.UUID, at time.Time) (*types.Visit, error) {
+	return f.transition(id, types.VisitExpected, func(v *types.Visit) {
+		v.Status = types.VisitCancelled
+		v.CancelledAt = &at
+	})
+}
+
+// transition applies the change to a visit in the from status, as the
+// repository's status-guarded updates do
+func (f *fakeVisitorRepo) transition(id uuid.UUID, from types.VisitStatus, apply func(*types.Visit)) (*types.Visit, error) {
+	visit, err := f.FindVisit(context.Background(), uuid.Nil, id)
+	if err != nil {
+		return nil, err
+	}
+	if visit.Status != from {
+		return nil, fmt.Errorf("visit is %s: %w", visit.Status, repository.ErrInvalidState)
+	}
+	apply(visit)
+	return visit, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) CheckPermission(ctx context.Context, permission string) error { return nil }
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent []push.Notification
+	done chan struct{}
+}
+
+func (n *fakeNotifier) Send(ctx context.Context, notification push.Notification) error {
+	n.mu.Lock()
+	n.sent = append(n.sent, notification)
+	n.mu.Unlock()
+	n.done <- struct{}{}
+	return nil
+}
+
+var testNow = time.Date(2025, 6, 15, 9, 30, 0, 0, time.UTC)
+
+func newTestService(repo *fakeVisitorRepo) *VisitorService {
+	svc := NewVisitorService(repo, allowAll{}, nil, nil)
+	svc.now = func() time.Time { return testNow }
+	return svc
+}
+
+func TestRegisterVisitRequiresActiveHost(t *testing.T) {
+	host := types.Host{EmployeeID: uuid.New(), Name: "Dana Reyes"}
+	repo := &fakeVisitorRepo{hosts: []types.Host{host}}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	_, err := svc.RegisterVisit(ctx, uuid.New(), uuid.New(), types.VisitRequest{HostEmployeeID: host.EmployeeID})
+	assert.ErrorIs(t, err, ErrInvalid, "visitor name required")
+
+	_, err = svc.RegisterVisit(ctx, uuid.New(), uuid.New(), types.VisitRequest{VisitorName: "Sam Ortiz", HostEmployeeID: uuid.New()})
+	assert.ErrorIs(t, err, ErrInvalid, "unknown host")
+
+	warehouseID := uuid.New()
+	_, err = svc.RegisterVisit(ctx, uuid.New(), uuid.New(), types.VisitRequest{
+		VisitorName: "Sam Ortiz", HostEmployeeID: host.EmployeeID, WarehouseID: &warehouseID,
+	})
+	assert.ErrorIs(t, err, ErrInvalid, "unknown warehouse")
+
+	// Walk-ins are expected now
+	visit, err := svc.RegisterVisit(ctx, uuid.New(), uuid.New(), types.VisitRequest{
+		VisitorName: " Sam Ortiz ", HostEmployeeID: host.EmployeeID, VehiclePlate: "ab 123",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Sam Ortiz", visit.VisitorName)
+	assert.Equal(t, "AB 123", visit.VehiclePlate)
+	assert.Equal(t, testNow, visit.ExpectedAt)
+	assert.Equal(t, types.VisitExpected, visit.Status)
+}
+
+func TestCheckInRequiresSafetyBriefingAndNotifiesHost(t *testing.T) {
+	userID := uuid.New()
+	host := types.Host{EmployeeID: uuid.New(), Name: "Dana Reyes", UserID: &userID}
+	visit := types.Visit{ID: uuid.New(), OrganizationID: uuid.New(), VisitorName: "Sam Ortiz",
+		VisitorCompany: "Acme Freight", HostEmployeeID: host.EmployeeID, Status: types.VisitExpected}
+	repo := &fakeVisitorRepo{visits: []types.Visit{visit}, hosts: []types.Host{host}}
+	notifier := &fakeNotifier{done: make(chan struct{}, 1)}
+	svc := newTestService(repo)
+	svc.SetPushNotifier(notifier)
+	ctx := context.Background()
+
+	_, err := svc.CheckIn(ctx, visit.OrganizationID, uuid.New(), visit.ID, types.CheckInRequest{BadgeNumber: "V-07"})
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Equal(t, types.VisitExpected, repo.visits[0].Status)
+
+	checkedIn, err := svc.CheckIn(ctx, visit.OrganizationID, uuid.New(), visit.ID, types.CheckInRequest{BadgeNumber: " V-07 ", SafetyBriefed: true})
+	require.NoError(t, err)
+	assert.Equal(t, types.VisitCheckedIn, checkedIn.Status)
+	assert.Equal(t, "V-07", checkedIn.BadgeNumber)
+	assert.Equal(t, testNow, *checkedIn.CheckedInAt)
+
+	select {
+	case <-notifier.done:
+	case <-time.After(time.Second):
+		t.Fatal("host was not notified")
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, []uuid.UUID{userID}, notifier.sent[0].UserIDs)
+	assert.Equal(t, "Sam Ortiz (Acme Freight) is at reception", notifier.sent[0].Body)
+	assert.Equal(t, visit.ID.String(), notifier.sent[0].Data["visit_id"])
+}
+
+func TestVisitTransitions(t *testing.T) {
+	host := types.Host{EmployeeID: uuid.New(), Name: "Dana Reyes"}
+	visit := types.Visit{ID: uuid.New(), VisitorName: "Sam Ortiz", HostEmployeeID: host.EmployeeID, Status: types.VisitExpected}
+	repo := &fakeVisitorRepo{visits: []types.Visit{visit}, hosts: []types.Host{host}}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	_, err := svc.CheckOut(ctx, uuid.Nil, uuid.New(), visit.ID, types.CheckOutRequest{})
+	assert.ErrorIs(t, err, repository.ErrInvalidState, "not checked in")
+
+	// A host without a user account is simply not notified
+	_, err = svc.CheckIn(ctx, uuid.Nil, uuid.New(), visit.ID, types.CheckInRequest{SafetyBriefed: true})
+	require.NoError(t, err)
+
+	data, err := svc.Load(ctx, uuid.Nil, visit.ID)
+	require.NoError(t, err, "badge printed while on site")
+	assert.Equal(t, "Sam Ortiz", data["visitor_name"])
+
+	_, err = svc.CancelVisit(ctx, uuid.Nil, uuid.New(), visit.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidState, "already checked in")
+
+	checkedOut, err := svc.CheckOut(ctx, uuid.Nil, uuid.New(), visit.ID, types.CheckOutRequest{BadgeReturned: true})
+	require.NoError(t, err)
+	assert.Equal(t, types.VisitCheckedOut, checkedOut.Status)
+
+	_, err = svc.Load(ctx, uuid.Nil, visit.ID)
+	assert.ErrorIs(t, err, ErrInvalid, "no badge after check-out")
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VisitStatus is where a visit is in its check-in lifecycle
+type VisitStatus string
+
+const (
+	VisitExpected   VisitStatus = "expected"
+	VisitCheckedIn  VisitStatus = "checked_in"
+	VisitCheckedOut VisitStatus = "checked_out"
+	VisitCancelled  VisitStatus = "cancelled"
+)
+
+// IsValid reports whether the status is known
+func (s VisitStatus) IsValid() bool {
+	switch s {
+	case VisitExpected, VisitCheckedIn, VisitCheckedOut, VisitCancelled:
+		return true
+	}
+	return false
+}
+
+// Visit is a visitor's visit to an office or warehouse, from registration
+// to check-out
+type Visit struct {
+	ID             uuid.UUID   `json:"id"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	VisitorName    string      `json:"visitor_name"`
+	VisitorCompany string      `json:"visitor_company,omitempty"`
+	VisitorEmail   string      `json:"visitor_email,omitempty"`
+	VisitorPhone   string      `json:"visitor_phone,omitempty"`
+	HostEmployeeID uuid.UUID   `json:"host_employee_id"`
+	HostName       string      `json:"host_name"`
+	WarehouseID    *uuid.UUID  `json:"warehouse_id,omitempty"`
+	WarehouseName  string      `json:"warehouse_name,omitempty"`
+	Purpose        string      `json:"purpose,omitempty"`
+	VehiclePlate   string      `json:"vehicle_plate,omitempty"`
+	ExpectedAt     time.Time   `json:"expected_at"`
+	Status         VisitStatus `json:"status"`
+	BadgeNumber    string      `json:"badge_number,omitempty"`
+	// IDDocument is the identity document checked at check-in, e.g. its
+	// type and last digits
+	IDDocument    string     `json:"id_document,omitempty"`
+	SafetyBriefed bool       `json:"safety_briefed"`
+	CheckedInAt   *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInBy   *uuid.UUID `json:"checked_in_by,omitempty"`
+	CheckedOutAt  *time.Time `json:"checked_out_at,omitempty"`
+	CheckedOutBy  *uuid.UUID `json:"checked_out_by,omitempty"`
+	BadgeReturned *bool      `json:"badge_returned,omitempty"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"`
+	CancelledBy   *uuid.UUID `json:"cancelled_by,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty"`
+}
+
+// VisitRequest registers a visit, or replaces the details of one that is
+// still expected. Without an expected time the visitor is expected now, as
+// for walk-ins registered at reception.
+type VisitRequest struct {
+	VisitorName    string     `json:"visitor_name"`
+	VisitorCompany string     `json:"visitor_company"`
+	VisitorEmail   string     `json:"visitor_email"`
+	VisitorPhone   string     `json:"visitor_phone"`
+	HostEmployeeID uuid.UUID  `json:"host_employee_id"`
+	WarehouseID    *uuid.UUID `json:"warehouse_id,omitempty"`
+	Purpose        string     `json:"purpose"`
+	VehiclePlate   string     `json:"vehicle_plate"`
+	ExpectedAt     *time.Time `json:"expected_at,omitempty"`
+	Notes          string     `json:"notes"`
+}
+
+// CheckInRequest checks a visitor in. The visitor must have been given the
+// site safety briefing.
+type CheckInRequest struct {
+	BadgeNumber   string `json:"badge_number"`
+	IDDocument    string `json:"id_document"`
+	SafetyBriefed bool   `json:"safety_briefed"`
+}
+
+// CheckOutRequest checks a visitor out
+type CheckOutRequest struct {
+	BadgeReturned bool `json:"badge_returned"`
+}
+
+// VisitFilter selects visits. From and To bound when the visitor was
+// expected, or checked in when they were.
+type VisitFilter struct {
+	OrganizationID uuid.UUID
+	Status         VisitStatus
+	WarehouseID    *uuid.UUID
+	HostEmployeeID *uuid.UUID
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+// Host is the employee a visitor comes to see
+type Host struct {
+	EmployeeID uuid.UUID
+	Name       string
+	// UserID is the host's user account, notified when their visitor
+	// arrives; nil when the employee has none
+	UserID *uuid.UUID
+}
@@ -0,0 +1,385 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// WebhooksHandler handles webhook subscriptions, their tests and the
+// delivery log
+type WebhooksHandler struct {
+	service *service.WebhooksService
+}
+
+func NewWebhooksHandler(service *service.WebhooksService) *WebhooksHandler {
+	return &WebhooksHandler{service: service}
+}
+
+func (h *WebhooksHandler) RegisterRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/webhook-events", h.ListEventTypes)
+
+	router.GET("/api/v1/webhooks", h.ListSubscriptions)
+	router.POST("/api/v1/webhooks", h.CreateSubscription)
+	router.GET("/api/v1/webhooks/:id", h.GetSubscription)
+	router.PUT("/api/v1/webhooks/:id", h.UpdateSubscription)
+	router.DELETE("/api/v1/webhooks/:id", h.DeleteSubscription)
+	router.POST("/api/v1/webhooks/:id/test", h.TestSubscription)
+	router.POST("/api/v1/webhooks/:id/rotate-secret", h.RotateSecret)
+
+	router.GET("/api/v1/webhook-deliveries", h.ListDeliveries)
+	router.GET("/api/v1/webhook-deliveries/:id", h.GetDelivery)
+	router.POST("/api/v1/webhook-deliveries/:id/redeliver", h.Redeliver)
+
+	router.GET("/api/v1/syslog-sink", h.GetSyslogSink)
+	router.PUT("/api/v1/syslog-sink", h.SetSyslogSink)
+	router.DELETE("/api/v1/syslog-sink", h.DeleteSyslogSink)
+	router.POST("/api/v1/syslog-sink/test", h.TestSyslogSink)
+}
+
+// ListEventTypes handles GET /api/v1/webhook-events, the events
+// subscriptions can name
+func (h *WebhooksHandler) ListEventTypes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if _, ok := auth.RequireAuthContext(w, r); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, types.EventTypes)
+}
+
+// ListSubscriptions handles GET /api/v1/webhooks
+func (h *WebhooksHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.service.ListSubscriptions(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscriptions)
+}
+
+// CreateSubscription handles POST /api/v1/webhooks
+func (h *WebhooksHandler) CreateSubscription(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.CreateSubscription(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// GetSubscription handles GET /api/v1/webhooks/:id
+func (h *WebhooksHandler) GetSubscription(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.GetSubscription(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// UpdateSubscription handles PUT /api/v1/webhooks/:id
+func (h *WebhooksHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.UpdateSubscription(r.Context(), authCtx.OrganizationID, authCtx.UserID, id, req)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// DeleteSubscription handles DELETE /api/v1/webhooks/:id
+func (h *WebhooksHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSubscription(r.Context(), authCtx.OrganizationID, id); err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestSubscription handles POST /api/v1/webhooks/:id/test, returning the
+// test delivery with the endpoint's answer
+func (h *WebhooksHandler) TestSubscription(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.service.TestSubscription(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// RotateSecret handles POST /api/v1/webhooks/:id/rotate-secret
+func (h *WebhooksHandler) RotateSecret(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.RotateSecret(r.Context(), authCtx.OrganizationID, authCtx.UserID, id)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// ListDeliveries handles GET /api/v1/webhook-deliveries with optional
+// webhook_id, event_type and status filters
+func (h *WebhooksHandler) ListDeliveries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter := types.DeliveryFilter{
+		OrganizationID: authCtx.OrganizationID,
+		EventType:      q.Get("event_type"),
+		Status:         types.DeliveryStatus(q.Get("status")),
+		Limit:          queryInt(q.Get("limit")),
+		Offset:         queryInt(q.Get("offset")),
+	}
+	if v := q.Get("webhook_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+			return
+		}
+		filter.SubscriptionID = &id
+	}
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// GetDelivery handles GET /api/v1/webhook-deliveries/:id
+func (h *WebhooksHandler) GetDelivery(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// Redeliver handles POST /api/v1/webhook-deliveries/:id/redeliver
+func (h *WebhooksHandler) Redeliver(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.service.Redeliver(r.Context(), authCtx.OrganizationID, id)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func queryInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// GetSyslogSink handles GET /api/v1/syslog-sink
+func (h *WebhooksHandler) GetSyslogSink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	sink, err := h.service.GetSyslogSink(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sink)
+}
+
+// SetSyslogSink handles PUT /api/v1/syslog-sink
+func (h *WebhooksHandler) SetSyslogSink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SyslogSinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sink, err := h.service.SetSyslogSink(r.Context(), authCtx.OrganizationID, authCtx.UserID, req)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sink)
+}
+
+// DeleteSyslogSink handles DELETE /api/v1/syslog-sink
+func (h *WebhooksHandler) DeleteSyslogSink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSyslogSink(r.Context(), authCtx.OrganizationID); err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestSyslogSink handles POST /api/v1/syslog-sink/test, returning the sink
+// with the outcome of the test message
+func (h *WebhooksHandler) TestSyslogSink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	authCtx, ok := auth.RequireAuthContext(w, r)
+	if !ok {
+		return
+	}
+
+	sink, err := h.service.TestSyslogSink(r.Context(), authCtx.OrganizationID)
+	if err != nil {
+		writeWebhooksError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sink)
+}
+
+func writeWebhooksError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrSyslogUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/service"
+	"github.com/KevTiv/alieze-erp/pkg/queue"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+)
+
+const JobTypeWebhooksDispatch = "webhooks.dispatch"
+
+// DispatchJobHandler handles queued passes posting due webhook deliveries
+type DispatchJobHandler struct {
+	webhooksService *service.WebhooksService
+	logger          *slog.Logger
+}
+
+func NewDispatchJobHandler(webhooksService *service.WebhooksService, logger *slog.Logger) *DispatchJobHandler {
+	return &DispatchJobHandler{
+		webhooksService: webhooksService,
+		logger:          logger,
+	}
+}
+
+// Handle posts the deliveries due
+func (h *DispatchJobHandler) Handle(ctx context.Context, job *queue.Job) error {
+	result, err := h.webhooksService.Dispatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to dispatch webhook deliveries: %w", err)
+	}
+	if result.Delivered+result.Retrying+result.Failed > 0 {
+		h.logger.Info("Webhook deliveries dispatched",
+			"delivered", result.Delivered,
+			"retrying", result.Retrying,
+			"failed", result.Failed)
+	}
+	return nil
+}
+
+// JobType returns the job type this handler processes
+func (h *DispatchJobHandler) JobType() string {
+	return JobTypeWebhooksDispatch
+}
+
+// Scheduler posts due webhook deliveries on a fixed interval, which also
+// retries the failed ones once their backoff has passed
+type Scheduler struct {
+	handler     *DispatchJobHandler
+	interval    time.Duration
+	coordinator *scheduler.Coordinator
+	logger      *slog.Logger
+}
+
+func NewScheduler(handler *DispatchJobHandler, interval time.Duration, coordinator *scheduler.Coordinator, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		handler:     handler,
+		interval:    interval,
+		coordinator: coordinator,
+		logger:      logger,
+	}
+}
+
+// Start posts due deliveries every interval until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	err := s.coordinator.Every(ctx, JobTypeWebhooksDispatch, s.interval, func(ctx context.Context, run scheduler.Run) error {
+		if err := s.handler.Handle(ctx, &queue.Job{JobType: JobTypeWebhooksDispatch, ScheduledAt: run.Slot, AttemptCount: run.Attempt}); err != nil {
+			s.logger.Error("Scheduled webhook dispatch failed", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to schedule webhook dispatch", "error", err)
+	}
+}
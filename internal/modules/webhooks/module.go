@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/handler"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/jobs"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/sender"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/service"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/events"
+	"github.com/KevTiv/alieze-erp/pkg/registry"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Bus events forwarded to webhooks, by the webhook event they become
+var forwardedEvents = map[string]string{
+	"crm.lead.created":       types.EventLeadCreated,
+	"crm.lead.stage_changed": types.EventLeadStageChanged,
+	"crm.lead.won":           types.EventLeadWon,
+	"crm.lead.lost":          types.EventLeadLost,
+	"crm.lead.assigned":      types.EventLeadAssigned,
+}
+
+// Security events recorded by the compliance module, by the webhook event
+// they become. They also go to the organization's syslog sink.
+var securityEvents = map[string]string{
+	"security.user.added":            types.EventSecurityUserAdded,
+	"security.user.removed":          types.EventSecurityUserRemoved,
+	"security.role.changed":          types.EventSecurityRoleChanged,
+	"security.api_key.created":       types.EventSecurityAPIKeyCreated,
+	"security.export.performed":      types.EventSecurityExportPerformed,
+	"security.impersonation.started": types.EventSecurityImpersonationStarted,
+	"security.impersonation.ended":   types.EventSecurityImpersonationEnded,
+}
+
+// eventLeadReassigned is split into one lead.assigned per lead
+const eventLeadReassigned = "crm.lead.reassigned"
+
+// WebhooksModule represents the outbound webhooks module
+type WebhooksModule struct {
+	webhooksService *service.WebhooksService
+	webhooksHandler *handler.WebhooksHandler
+	scheduler       *jobs.Scheduler
+	logger          *slog.Logger
+}
+
+// NewWebhooksModule creates a new webhooks module
+func NewWebhooksModule() *WebhooksModule {
+	return &WebhooksModule{}
+}
+
+// Name returns the module name
+func (m *WebhooksModule) Name() string {
+	return "webhooks"
+}
+
+// Init initializes the webhooks module
+func (m *WebhooksModule) Init(ctx context.Context, deps registry.Dependencies) error {
+	// Initialize logger
+	m.logger = deps.Logger.With("module", "webhooks")
+	m.logger.Info("Initializing webhooks module")
+
+	// Create repositories
+	webhooksRepo := repository.NewWebhooksRepository(deps.DB)
+
+	// Create services
+	authAdapter := auth.NewPolicyAuthAdapterWithRules(deps.PolicyEngine, deps.RuleEngine)
+	m.webhooksService = service.NewWebhooksService(webhooksRepo, authAdapter, sender.NewHTTPSender(service.PostTimeout), m.logger)
+	m.webhooksService.SetSyslogSender(sender.NewSyslogSender(service.PostTimeout))
+
+	// Create scheduled dispatch
+	dispatchJobHandler := jobs.NewDispatchJobHandler(m.webhooksService, m.logger)
+	m.scheduler = jobs.NewScheduler(dispatchJobHandler, service.DispatchInterval, deps.Scheduler, m.logger)
+	m.scheduler.Start(ctx)
+
+	// Create handlers
+	m.webhooksHandler = handler.NewWebhooksHandler(m.webhooksService)
+
+	m.logger.Info("Webhooks module initialized successfully")
+	return nil
+}
+
+// RegisterRoutes registers webhooks module routes
+func (m *WebhooksModule) RegisterRoutes(router interface{}) {
+	if m.webhooksHandler != nil && router != nil {
+		if r, ok := router.(*httprouter.Router); ok {
+			m.webhooksHandler.RegisterRoutes(r)
+		}
+	}
+}
+
+// RegisterEventHandlers queues deliveries of the lead and security events
+// subscriptions can name
+func (m *WebhooksModule) RegisterEventHandlers(bus interface{}) {
+	eventBus, ok := bus.(*events.Bus)
+	if !ok || m.webhooksService == nil {
+		return
+	}
+
+	for source, eventType := range forwardedEvents {
+		eventBus.Subscribe(source, m.handleEvent(eventType))
+	}
+	eventBus.Subscribe(eventLeadReassigned, m.handleReassigned)
+	for source, eventType := range securityEvents {
+		eventBus.Subscribe(source, m.handleSecurityEvent(eventType))
+	}
+
+	m.logger.Info("Webhooks module event handlers registered")
+}
+
+// handleEvent queues deliveries of an event as eventType, with the event's
+// payload as their data. Failures are logged rather than returned so they
+// never fail the change that raised the event.
+func (m *WebhooksModule) handleEvent(eventType string) events.HandlerFunc {
+	return func(ctx context.Context, event events.Event) error {
+		data, err := json.Marshal(event.Payload)
+		if err != nil {
+			m.logger.Error("Failed to encode webhook event", "event", event.Type, "error", err)
+			return nil
+		}
+
+		var payload struct {
+			OrganizationID uuid.UUID `json:"organization_id"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			m.logger.Error("Failed to decode webhook event", "event", event.Type, "error", err)
+			return nil
+		}
+
+		m.enqueue(ctx, payload.OrganizationID, eventType, data, event)
+		return nil
+	}
+}
+
+// handleSecurityEvent queues deliveries of a security event as eventType
+// and sends it to the organization's syslog sink
+func (m *WebhooksModule) handleSecurityEvent(eventType string) events.HandlerFunc {
+	return func(ctx context.Context, event events.Event) error {
+		data, err := json.Marshal(event.Payload)
+		if err != nil {
+			m.logger.Error("Failed to encode webhook event", "event", event.Type, "error", err)
+			return nil
+		}
+
+		var payload struct {
+			OrganizationID uuid.UUID `json:"organization_id"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			m.logger.Error("Failed to decode webhook event", "event", event.Type, "error", err)
+			return nil
+		}
+		if payload.OrganizationID == uuid.Nil {
+			return nil
+		}
+
+		m.enqueue(ctx, payload.OrganizationID, eventType, data, event)
+		if err := m.webhooksService.ForwardToSyslog(ctx, payload.OrganizationID, eventType, data, event.Timestamp, event.Sandbox); err != nil {
+			m.logger.Warn("Failed to send security event to syslog", "event", eventType, "organization_id", payload.OrganizationID, "error", err)
+		}
+		return nil
+	}
+}
+
+// handleReassigned queues a lead.assigned delivery for each lead a bulk
+// reassignment handed to a new owner
+func (m *WebhooksModule) handleReassigned(ctx context.Context, event events.Event) error {
+	var notice struct {
+		OrganizationID uuid.UUID   `json:"organization_id"`
+		UserID         uuid.UUID   `json:"user_id"`
+		FromUserID     uuid.UUID   `json:"from_user_id"`
+		LeadIDs        []uuid.UUID `json:"lead_ids"`
+		Reason         string      `json:"reason"`
+		AssignedAt     time.Time   `json:"assigned_at"`
+	}
+	bytes, err := json.Marshal(event.Payload)
+	if err == nil {
+		err = json.Unmarshal(bytes, &notice)
+	}
+	if err != nil {
+		m.logger.Error("Failed to decode webhook event", "event", event.Type, "error", err)
+		return nil
+	}
+
+	for _, leadID := range notice.LeadIDs {
+		// Shaped like crm.lead.assigned so subscribers see one payload
+		data, err := json.Marshal(map[string]interface{}{
+			"organization_id":      notice.OrganizationID,
+			"lead_id":              leadID,
+			"assigned_to":          notice.UserID,
+			"previous_assigned_to": notice.FromUserID,
+			"reason":               notice.Reason,
+			"assigned_at":          notice.AssignedAt,
+		})
+		if err != nil {
+			m.logger.Error("Failed to encode webhook event", "event", event.Type, "error", err)
+			return nil
+		}
+		m.enqueue(ctx, notice.OrganizationID, types.EventLeadAssigned, data, event)
+	}
+	return nil
+}
+
+func (m *WebhooksModule) enqueue(ctx context.Context, orgID uuid.UUID, eventType string, data json.RawMessage, event events.Event) {
+	if orgID == uuid.Nil {
+		return
+	}
+	if _, err := m.webhooksService.Enqueue(ctx, orgID, eventType, data, event.Timestamp, event.Sandbox); err != nil {
+		m.logger.Error("Failed to queue webhook deliveries", "event", eventType, "organization_id", orgID, "error", err)
+	}
+}
+
+// Health checks the health of the webhooks module
+func (m *WebhooksModule) Health() error {
+	return nil
+}
@@ -0,0 +1,447 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a subscription, delivery or syslog sink
+// does not exist
+var ErrNotFound = errors.New("not found")
+
+// WebhooksRepo defines the interface for webhooks repository operations
+type WebhooksRepo interface {
+	ListSubscriptions(ctx context.Context, orgID uuid.UUID) ([]types.Subscription, error)
+	FindSubscription(ctx context.Context, orgID, id uuid.UUID) (*types.Subscription, error)
+	CreateSubscription(ctx context.Context, orgID, userID uuid.UUID, request types.SubscriptionRequest, secret string) (*types.Subscription, error)
+	UpdateSubscription(ctx context.Context, orgID, userID, id uuid.UUID, request types.SubscriptionRequest) (*types.Subscription, error)
+	DeleteSubscription(ctx context.Context, orgID, id uuid.UUID) error
+	SetSecret(ctx context.Context, orgID, userID, id uuid.UUID, secret string) error
+	SubscriptionSecret(ctx context.Context, id uuid.UUID) (string, error)
+	SubscribedTo(ctx context.Context, orgID uuid.UUID, eventType string) ([]types.Subscription, error)
+	CreateDelivery(ctx context.Context, delivery types.Delivery) (*types.Delivery, error)
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]types.Delivery, error)
+	RecordAttempt(ctx context.Context, id uuid.UUID, attempt types.Attempt) (*types.Delivery, error)
+	ListDeliveries(ctx context.Context, filter types.DeliveryFilter) ([]types.Delivery, error)
+	FindDelivery(ctx context.Context, orgID, id uuid.UUID) (*types.Delivery, error)
+	Redeliver(ctx context.Context, orgID, id uuid.UUID, at time.Time) (*types.Delivery, error)
+	PurgeDeliveries(ctx context.Context, before time.Time) (int64, error)
+	FindSyslogSink(ctx context.Context, orgID uuid.UUID) (*types.SyslogSink, error)
+	UpsertSyslogSink(ctx context.Context, orgID, userID uuid.UUID, request types.SyslogSinkRequest) (*types.SyslogSink, error)
+	DeleteSyslogSink(ctx context.Context, orgID uuid.UUID) error
+	RecordSyslogSend(ctx context.Context, orgID uuid.UUID, at time.Time, sendErr string) error
+}
+
+// WebhooksRepository stores webhook subscriptions and their deliveries
+type WebhooksRepository struct {
+	db *sql.DB
+}
+
+// Ensure WebhooksRepository implements WebhooksRepo interface
+var _ WebhooksRepo = &WebhooksRepository{}
+
+func NewWebhooksRepository(db *sql.DB) *WebhooksRepository {
+	return &WebhooksRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(...interface{}) error
+}
+
+func nullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	id := v.UUID
+	return &id
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+// subscriptionColumns leave out the secret, which is only read to sign
+const subscriptionColumns = `
+	id, organization_id, name, url, event_types, active, created_at, updated_at, created_by, updated_by
+`
+
+func scanSubscription(row rowScanner) (*types.Subscription, error) {
+	var sub types.Subscription
+	var createdBy, updatedBy uuid.NullUUID
+	err := row.Scan(&sub.ID, &sub.OrganizationID, &sub.Name, &sub.URL, pq.Array(&sub.EventTypes), &sub.Active,
+		&sub.CreatedAt, &sub.UpdatedAt, &createdBy, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	sub.CreatedBy = nullUUID(createdBy)
+	sub.UpdatedBy = nullUUID(updatedBy)
+	return &sub, nil
+}
+
+func (r *WebhooksRepository) listSubscriptions(ctx context.Context, query string, args ...interface{}) ([]types.Subscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []types.Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *WebhooksRepository) ListSubscriptions(ctx context.Context, orgID uuid.UUID) ([]types.Subscription, error) {
+	return r.listSubscriptions(ctx, `
+		SELECT `+subscriptionColumns+` FROM webhook_subscriptions
+		WHERE organization_id = $1
+		ORDER BY name, id
+	`, orgID)
+}
+
+func (r *WebhooksRepository) FindSubscription(ctx context.Context, orgID, id uuid.UUID) (*types.Subscription, error) {
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx, `
+		SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1 AND organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook subscription %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *WebhooksRepository) CreateSubscription(ctx context.Context, orgID, userID uuid.UUID, request types.SubscriptionRequest, secret string) (*types.Subscription, error) {
+	active := request.Active == nil || *request.Active
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (organization_id, name, url, secret, event_types, active, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING `+subscriptionColumns,
+		orgID, request.Name, request.URL, secret, pq.Array(request.EventTypes), active, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *WebhooksRepository) UpdateSubscription(ctx context.Context, orgID, userID, id uuid.UUID, request types.SubscriptionRequest) (*types.Subscription, error) {
+	active := request.Active == nil || *request.Active
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions SET
+			name = $3, url = $4, event_types = $5, active = $6, updated_by = $7, updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+subscriptionColumns,
+		id, orgID, request.Name, request.URL, pq.Array(request.EventTypes), active, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook subscription %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// DeleteSubscription removes a subscription with its deliveries
+func (r *WebhooksRepository) DeleteSubscription(ctx context.Context, orgID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("webhook subscription %w", ErrNotFound)
+	}
+	return nil
+}
+
+// SetSecret replaces the secret deliveries are signed with
+func (r *WebhooksRepository) SetSecret(ctx context.Context, orgID, userID, id uuid.UUID, secret string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_subscriptions SET secret = $3, updated_by = $4, updated_at = now()
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("webhook subscription %w", ErrNotFound)
+	}
+	return nil
+}
+
+// SubscriptionSecret returns the secret a subscription's deliveries are
+// signed with
+func (r *WebhooksRepository) SubscriptionSecret(ctx context.Context, id uuid.UUID) (string, error) {
+	var secret string
+	err := r.db.QueryRowContext(ctx, `SELECT secret FROM webhook_subscriptions WHERE id = $1`, id).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("webhook subscription %w", ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// SubscribedTo returns the organization's active subscriptions naming the
+// event type
+func (r *WebhooksRepository) SubscribedTo(ctx context.Context, orgID uuid.UUID, eventType string) ([]types.Subscription, error) {
+	return r.listSubscriptions(ctx, `
+		SELECT `+subscriptionColumns+` FROM webhook_subscriptions
+		WHERE organization_id = $1 AND active AND $2 = ANY(event_types)
+		ORDER BY id
+	`, orgID, eventType)
+}
+
+const deliveryColumns = `
+	id, organization_id, subscription_id, event_id, event_type, payload, sandbox, occurred_at, status, attempts,
+	next_attempt_at, last_attempt_at, response_status, COALESCE(response_body, ''), COALESCE(error, ''),
+	delivered_at, created_at
+`
+
+func scanDelivery(row rowScanner) (*types.Delivery, error) {
+	var d types.Delivery
+	var payload []byte
+	var lastAttempt, delivered sql.NullTime
+	var responseStatus sql.NullInt64
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Sandbox,
+		&d.OccurredAt, &d.Status, &d.Attempts, &d.NextAttemptAt, &lastAttempt, &responseStatus, &d.ResponseBody,
+		&d.Error, &delivered, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	d.LastAttemptAt = nullTime(lastAttempt)
+	d.DeliveredAt = nullTime(delivered)
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		d.ResponseStatus = &status
+	}
+	return &d, nil
+}
+
+func (r *WebhooksRepository) listDeliveries(ctx context.Context, query string, args ...interface{}) ([]types.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhooksRepository) CreateDelivery(ctx context.Context, delivery types.Delivery) (*types.Delivery, error) {
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (
+			organization_id, subscription_id, event_id, event_type, payload, sandbox, occurred_at, next_attempt_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+deliveryColumns,
+		delivery.OrganizationID, delivery.SubscriptionID, delivery.EventID, delivery.EventType,
+		[]byte(delivery.Payload), delivery.Sandbox, delivery.OccurredAt, delivery.NextAttemptAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+// DueDeliveries returns the pending deliveries of active subscriptions
+// whose next attempt is due, oldest first. Deliveries of paused
+// subscriptions wait for them to be resumed.
+func (r *WebhooksRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]types.Delivery, error) {
+	return r.listDeliveries(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+			AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active)
+		ORDER BY next_attempt_at, created_at
+		LIMIT $2
+	`, now, limit)
+}
+
+// RecordAttempt records the outcome of posting a delivery once
+func (r *WebhooksRepository) RecordAttempt(ctx context.Context, id uuid.UUID, attempt types.Attempt) (*types.Delivery, error) {
+	var responseStatus sql.NullInt64
+	if attempt.ResponseStatus != 0 {
+		responseStatus = sql.NullInt64{Int64: int64(attempt.ResponseStatus), Valid: true}
+	}
+	var deliveredAt sql.NullTime
+	if attempt.Status == types.DeliveryDelivered {
+		deliveredAt = sql.NullTime{Time: attempt.At, Valid: true}
+	}
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $2, attempts = attempts + 1, last_attempt_at = $3, next_attempt_at = $4,
+			response_status = $5, response_body = NULLIF($6, ''), error = NULLIF($7, ''), delivered_at = $8
+		WHERE id = $1
+		RETURNING `+deliveryColumns,
+		id, attempt.Status, attempt.At, attempt.NextAttemptAt, responseStatus, attempt.ResponseBody, attempt.Error,
+		deliveredAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhooksRepository) ListDeliveries(ctx context.Context, filter types.DeliveryFilter) ([]types.Delivery, error) {
+	where := []string{"organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.SubscriptionID != nil {
+		args = append(args, *filter.SubscriptionID)
+		where = append(where, fmt.Sprintf("subscription_id = $%d", len(args)))
+	}
+	if filter.EventType != "" {
+		args = append(args, filter.EventType)
+		where = append(where, fmt.Sprintf("event_type = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	return r.listDeliveries(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(len(args)-1)+` OFFSET $`+fmt.Sprint(len(args)), args...)
+}
+
+func (r *WebhooksRepository) FindDelivery(ctx context.Context, orgID, id uuid.UUID) (*types.Delivery, error) {
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND organization_id = $2
+	`, id, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts
+func (r *WebhooksRepository) Redeliver(ctx context.Context, orgID, id uuid.UUID, at time.Time) (*types.Delivery, error) {
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $3
+		WHERE id = $1 AND organization_id = $2
+		RETURNING `+deliveryColumns,
+		id, orgID, at))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook delivery %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue webhook redelivery: %w", err)
+	}
+	return d, nil
+}
+
+// PurgeDeliveries removes the deliveries created before the cutoff
+func (r *WebhooksRepository) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+const syslogSinkColumns = `
+	organization_id, address, protocol, active, last_sent_at, COALESCE(last_error, ''), created_at, updated_at,
+	updated_by
+`
+
+func scanSyslogSink(row rowScanner) (*types.SyslogSink, error) {
+	var sink types.SyslogSink
+	var lastSent sql.NullTime
+	var updatedBy uuid.NullUUID
+	err := row.Scan(&sink.OrganizationID, &sink.Address, &sink.Protocol, &sink.Active, &lastSent, &sink.LastError,
+		&sink.CreatedAt, &sink.UpdatedAt, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	sink.LastSentAt = nullTime(lastSent)
+	sink.UpdatedBy = nullUUID(updatedBy)
+	return &sink, nil
+}
+
+func (r *WebhooksRepository) FindSyslogSink(ctx context.Context, orgID uuid.UUID) (*types.SyslogSink, error) {
+	sink, err := scanSyslogSink(r.db.QueryRowContext(ctx, `
+		SELECT `+syslogSinkColumns+` FROM webhook_syslog_sinks WHERE organization_id = $1
+	`, orgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("syslog sink %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find syslog sink: %w", err)
+	}
+	return sink, nil
+}
+
+// UpsertSyslogSink creates or replaces the organization's syslog sink,
+// clearing the outcome of the last send
+func (r *WebhooksRepository) UpsertSyslogSink(ctx context.Context, orgID, userID uuid.UUID, request types.SyslogSinkRequest) (*types.SyslogSink, error) {
+	active := request.Active == nil || *request.Active
+	sink, err := scanSyslogSink(r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_syslog_sinks (organization_id, address, protocol, active, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			address = EXCLUDED.address, protocol = EXCLUDED.protocol, active = EXCLUDED.active,
+			last_sent_at = NULL, last_error = NULL, updated_by = EXCLUDED.updated_by, updated_at = now()
+		RETURNING `+syslogSinkColumns,
+		orgID, request.Address, request.Protocol, active, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save syslog sink: %w", err)
+	}
+	return sink, nil
+}
+
+func (r *WebhooksRepository) DeleteSyslogSink(ctx context.Context, orgID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_syslog_sinks WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete syslog sink: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("syslog sink %w", ErrNotFound)
+	}
+	return nil
+}
+
+// RecordSyslogSend records the outcome of the last send to a syslog sink;
+// an empty error is a success
+func (r *WebhooksRepository) RecordSyslogSend(ctx context.Context, orgID uuid.UUID, at time.Time, sendErr string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_syslog_sinks SET
+			last_sent_at = CASE WHEN $3 = '' THEN $2 ELSE last_sent_at END, last_error = NULLIF($3, '')
+		WHERE organization_id = $1
+	`, orgID, at, sendErr)
+	if err != nil {
+		return fmt.Errorf("failed to record syslog send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxResponseBody bounds how much of an endpoint's answer is kept in the
+// delivery log
+const maxResponseBody = 1024
+
+// ErrPrivateAddress is returned when an endpoint or syslog sink resolves to
+// a loopback, private or link-local address, which webhooks must not reach
+var ErrPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+// HTTPSender posts webhook deliveries over HTTP. It refuses to connect to
+// private networks, so subscriptions cannot be pointed at internal services,
+// and does not follow redirects.
+type HTTPSender struct {
+	client *http.Client
+}
+
+// publicDialer connects to public addresses only. The address is checked
+// once resolved, so DNS cannot point around it.
+func publicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+}
+
+// NewHTTPSender creates a sender whose posts time out after timeout
+func NewHTTPSender(timeout time.Duration) *HTTPSender {
+	dialer := publicDialer(timeout)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &HTTPSender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Post posts body to url with the headers, returning the response status
+// and the start of the response body
+func (s *HTTPSender) Post(ctx context.Context, url string, headers map[string]string, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("User-Agent", "alieze-erp-webhooks/1")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(bytes.TrimSpace(response)), nil
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
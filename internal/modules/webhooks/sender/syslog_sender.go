@@ -0,0 +1,64 @@
+package sender
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+)
+
+// SyslogSender sends messages to syslog collectors, over UDP one message
+// per datagram, and over TCP and TLS framed by octet counting (RFC 6587).
+// Like HTTPSender, it refuses to connect to private networks.
+type SyslogSender struct {
+	timeout time.Duration
+}
+
+// NewSyslogSender creates a sender whose sends time out after timeout
+func NewSyslogSender(timeout time.Duration) *SyslogSender {
+	return &SyslogSender{timeout: timeout}
+}
+
+// Send sends one message to the collector at address. A connection is made
+// per message, as security events are few.
+func (s *SyslogSender) Send(ctx context.Context, protocol types.SyslogProtocol, address string, message []byte) error {
+	dialer := publicDialer(s.timeout)
+	var conn net.Conn
+	var err error
+	switch protocol {
+	case types.SyslogUDP:
+		conn, err = dialer.DialContext(ctx, "udp", address)
+	case types.SyslogTCP:
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	case types.SyslogTLS:
+		host, _, _ := net.SplitHostPort(address)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", address)
+	default:
+		return fmt.Errorf("unknown syslog protocol %q", protocol)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog sink: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	frame := message
+	if protocol != types.SyslogUDP {
+		frame = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to send to syslog sink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// syslogPriority is facility authpriv (10) at severity notice (5)
+	syslogPriority = 10*8 + 5
+	// syslogAppName is the APP-NAME of the messages
+	syslogAppName = "alieze-erp"
+	// syslogTimeFormat is the RFC 5424 TIMESTAMP, to the microsecond
+	syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// ErrSyslogUnavailable is returned when a syslog sink is tested but no
+// sender is configured
+var ErrSyslogUnavailable = errors.New("syslog sending is not configured")
+
+// SyslogSender sends one message to a syslog collector
+type SyslogSender interface {
+	Send(ctx context.Context, protocol types.SyslogProtocol, address string, message []byte) error
+}
+
+// SetSyslogSender sets how security events reach syslog sinks. Without one,
+// sinks receive nothing.
+func (s *WebhooksService) SetSyslogSender(sender SyslogSender) {
+	s.syslog = sender
+}
+
+// GetSyslogSink returns the organization's syslog sink
+func (s *WebhooksService) GetSyslogSink(ctx context.Context, orgID uuid.UUID) (*types.SyslogSink, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindSyslogSink(ctx, orgID)
+}
+
+// SetSyslogSink creates or replaces the organization's syslog sink
+func (s *WebhooksService) SetSyslogSink(ctx context.Context, orgID, userID uuid.UUID, req types.SyslogSinkRequest) (*types.SyslogSink, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizeSyslogSink(&req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpsertSyslogSink(ctx, orgID, userID, req)
+}
+
+// DeleteSyslogSink stops sending the organization's security events to
+// syslog
+func (s *WebhooksService) DeleteSyslogSink(ctx context.Context, orgID uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteSyslogSink(ctx, orgID)
+}
+
+// TestSyslogSink sends a webhook.test message to the organization's syslog
+// sink right away, paused or not, and returns the sink with the outcome
+func (s *WebhooksService) TestSyslogSink(ctx context.Context, orgID uuid.UUID) (*types.SyslogSink, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if s.syslog == nil {
+		return nil, ErrSyslogUnavailable
+	}
+	sink, err := s.repo.FindSyslogSink(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"message": "Test message of the syslog sink"})
+	if err != nil {
+		return nil, err
+	}
+	s.sendSyslog(ctx, *sink, types.EventTest, data, s.now(), false)
+	return s.repo.FindSyslogSink(ctx, orgID)
+}
+
+// ForwardToSyslog sends a security event to the organization's syslog sink,
+// if it has an active one. Sends are not retried; a failure is recorded on
+// the sink and returned.
+func (s *WebhooksService) ForwardToSyslog(ctx context.Context, orgID uuid.UUID, eventType string, data json.RawMessage, occurredAt time.Time, sandbox bool) error {
+	if s.syslog == nil {
+		return nil
+	}
+	sink, err := s.repo.FindSyslogSink(ctx, orgID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !sink.Active {
+		return nil
+	}
+	if occurredAt.IsZero() {
+		occurredAt = s.now()
+	}
+	return s.sendSyslog(ctx, *sink, eventType, data, occurredAt, sandbox)
+}
+
+// sendSyslog sends an event to a sink once and records the outcome
+func (s *WebhooksService) sendSyslog(ctx context.Context, sink types.SyslogSink, eventType string, data json.RawMessage, occurredAt time.Time, sandbox bool) error {
+	message, err := FormatSyslog(types.Envelope{
+		ID:             uuid.New(),
+		Type:           eventType,
+		OrganizationID: sink.OrganizationID,
+		OccurredAt:     occurredAt,
+		Sandbox:        sandbox,
+		Data:           data,
+	})
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, PostTimeout)
+	sendErr := s.syslog.Send(sendCtx, sink.Protocol, sink.Address, message)
+	cancel()
+
+	recorded := ""
+	if sendErr != nil {
+		recorded = sendErr.Error()
+	}
+	if err := s.repo.RecordSyslogSend(ctx, sink.OrganizationID, s.now(), recorded); err != nil {
+		s.logger.Warn("Failed to record syslog send", "organization_id", sink.OrganizationID, "error", err)
+	}
+	return sendErr
+}
+
+// FormatSyslog formats an event as an RFC 5424 message whose MSGID is the
+// event type and whose MSG is the envelope posted to webhooks, as JSON
+func FormatSyslog(envelope types.Envelope) ([]byte, error) {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode syslog message: %w", err)
+	}
+	header := fmt.Sprintf("<%d>1 %s - %s - %s - ",
+		syslogPriority, envelope.OccurredAt.UTC().Format(syslogTimeFormat), syslogAppName, envelope.Type)
+	return append([]byte(header), body...), nil
+}
+
+func normalizeSyslogSink(req *types.SyslogSinkRequest) error {
+	req.Address = strings.TrimSpace(req.Address)
+	if req.Protocol == "" {
+		req.Protocol = types.SyslogTLS
+	}
+	if !req.Protocol.IsValid() {
+		return fmt.Errorf("%w: protocol must be udp, tcp or tls", ErrInvalid)
+	}
+
+	host, port, err := net.SplitHostPort(req.Address)
+	if err != nil || host == "" {
+		return fmt.Errorf("%w: address must be host:port", ErrInvalid)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%w: address must have a port between 1 and 65535", ErrInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/repository"
+	"github.com/KevTiv/alieze-erp/internal/modules/webhooks/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DispatchInterval is how often due deliveries are posted
+	DispatchInterval = time.Minute
+	// MaxAttempts bounds the posts of a delivery before it is marked failed
+	MaxAttempts = 8
+	// BaseRetryDelay is the wait after a delivery's first failed post; it
+	// doubles with every further failure
+	BaseRetryDelay = time.Minute
+	// MaxRetryDelay caps the wait between two posts of a delivery
+	MaxRetryDelay = 6 * time.Hour
+	// DeliveryRetention is how long the delivery log is kept
+	DeliveryRetention = 30 * 24 * time.Hour
+	// DefaultPageSize is the page size of the delivery log
+	DefaultPageSize = 100
+	// MaxPageSize bounds the page size of the delivery log
+	MaxPageSize = 500
+	// dispatchBatch bounds the deliveries posted by one dispatch pass
+	dispatchBatch = 200
+	// PostTimeout bounds one post of a delivery
+	PostTimeout = 10 * time.Second
+	// secretPrefix marks webhook secrets so they are recognized when leaked
+	secretPrefix = "whsec_"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrInvalid wraps validation failures of subscriptions and filters
+var ErrInvalid = errors.New("invalid request")
+
+// AuthService defines the permission check used by the webhooks service
+type AuthService interface {
+	CheckPermission(ctx context.Context, permission string) error
+}
+
+// Sender posts a delivery's body to an endpoint, returning the response
+// status and the start of the response body
+type Sender interface {
+	Post(ctx context.Context, url string, headers map[string]string, body []byte) (int, string, error)
+}
+
+// WebhooksService forwards the organization's events to the endpoints
+// subscribed to them: each event becomes a signed delivery per
+// subscription, posted and retried with backoff, with a delivery log
+type WebhooksService struct {
+	repo        repository.WebhooksRepo
+	authService AuthService
+	sender      Sender
+	syslog      SyslogSender
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewWebhooksService(repo repository.WebhooksRepo, authService AuthService, sender Sender, logger *slog.Logger) *WebhooksService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &WebhooksService{
+		repo:        repo,
+		authService: authService,
+		sender:      sender,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// ListSubscriptions returns the organization's webhook subscriptions
+func (s *WebhooksService) ListSubscriptions(ctx context.Context, orgID uuid.UUID) ([]types.Subscription, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.ListSubscriptions(ctx, orgID)
+}
+
+// GetSubscription returns a webhook subscription
+func (s *WebhooksService) GetSubscription(ctx context.Context, orgID, id uuid.UUID) (*types.Subscription, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindSubscription(ctx, orgID, id)
+}
+
+// CreateSubscription creates a webhook subscription. Its signing secret is
+// returned this once.
+func (s *WebhooksService) CreateSubscription(ctx context.Context, orgID, userID uuid.UUID, req types.SubscriptionRequest) (*types.Subscription, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizeSubscription(&req); err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.CreateSubscription(ctx, orgID, userID, req, secret)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+// UpdateSubscription replaces a webhook subscription's endpoint, events and
+// state. Its secret stays.
+func (s *WebhooksService) UpdateSubscription(ctx context.Context, orgID, userID, id uuid.UUID, req types.SubscriptionRequest) (*types.Subscription, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if err := normalizeSubscription(&req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateSubscription(ctx, orgID, userID, id, req)
+}
+
+// DeleteSubscription removes a webhook subscription with its deliveries
+func (s *WebhooksService) DeleteSubscription(ctx context.Context, orgID, id uuid.UUID) error {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.DeleteSubscription(ctx, orgID, id)
+}
+
+// RotateSecret replaces a subscription's signing secret, returning the new
+// one this once. Deliveries posted from now on are signed with it.
+func (s *WebhooksService) RotateSecret(ctx context.Context, orgID, userID, id uuid.UUID) (*types.Subscription, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetSecret(ctx, orgID, userID, id, secret); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.FindSubscription(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+// TestSubscription posts a webhook.test event to a subscription right away
+// and returns how it went. Test deliveries are not retried.
+func (s *WebhooksService) TestSubscription(ctx context.Context, orgID, id uuid.UUID) (*types.Delivery, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	sub, err := s.repo.FindSubscription(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"subscription_id": sub.ID,
+		"message":         "Test delivery of " + sub.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	delivery, err := s.repo.CreateDelivery(ctx, types.Delivery{
+		OrganizationID: orgID,
+		SubscriptionID: sub.ID,
+		EventID:        uuid.New(),
+		EventType:      types.EventTest,
+		Payload:        payload,
+		OccurredAt:     now,
+		NextAttemptAt:  now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.post(ctx, sub.URL, *delivery)
+}
+
+// ListDeliveries returns the delivery log, newest first
+func (s *WebhooksService) ListDeliveries(ctx context.Context, filter types.DeliveryFilter) ([]types.Delivery, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalid, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.ListDeliveries(ctx, filter)
+}
+
+// GetDelivery returns a delivery from the log
+func (s *WebhooksService) GetDelivery(ctx context.Context, orgID, id uuid.UUID) (*types.Delivery, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:read"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.FindDelivery(ctx, orgID, id)
+}
+
+// Redeliver queues a delivery to be posted again on the next dispatch
+// pass, with a fresh set of attempts
+func (s *WebhooksService) Redeliver(ctx context.Context, orgID, id uuid.UUID) (*types.Delivery, error) {
+	if err := s.authService.CheckPermission(ctx, "webhooks:manage"); err != nil {
+		return nil, fmt.Errorf("permission denied: %w", err)
+	}
+	return s.repo.Redeliver(ctx, orgID, id, s.now())
+}
+
+// Enqueue creates a delivery of an event for each of the organization's
+// active subscriptions naming its type, returning how many were created.
+// The deliveries share one event ID.
+func (s *WebhooksService) Enqueue(ctx context.Context, orgID uuid.UUID, eventType string, data json.RawMessage, occurredAt time.Time, sandbox bool) (int, error) {
+	subs, err := s.repo.SubscribedTo(ctx, orgID, eventType)
+	if err != nil {
+		return 0, err
+	}
+	if occurredAt.IsZero() {
+		occurredAt = s.now()
+	}
+
+	eventID := uuid.New()
+	created := 0
+	for _, sub := range subs {
+		_, err := s.repo.CreateDelivery(ctx, types.Delivery{
+			OrganizationID: orgID,
+			SubscriptionID: sub.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        data,
+			Sandbox:        sandbox,
+			OccurredAt:     occurredAt,
+			NextAttemptAt:  s.now(),
+		})
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// Dispatch posts the deliveries due and purges the delivery log past its
+// retention
+func (s *WebhooksService) Dispatch(ctx context.Context) (*types.DispatchResult, error) {
+	due, err := s.repo.DueDeliveries(ctx, s.now(), dispatchBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.DispatchResult{}
+	subs := make(map[uuid.UUID]*types.Subscription)
+	for _, delivery := range due {
+		sub, ok := subs[delivery.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.FindSubscription(ctx, delivery.OrganizationID, delivery.SubscriptionID)
+			if err != nil {
+				return result, err
+			}
+			subs[delivery.SubscriptionID] = sub
+		}
+
+		posted, err := s.post(ctx, sub.URL, delivery)
+		if err != nil {
+			return result, err
+		}
+		switch posted.Status {
+		case types.DeliveryDelivered:
+			result.Delivered++
+		case types.DeliveryFailed:
+			result.Failed++
+		default:
+			result.Retrying++
+		}
+	}
+
+	if _, err := s.repo.PurgeDeliveries(ctx, s.now().Add(-DeliveryRetention)); err != nil {
+		s.logger.Warn("Failed to purge webhook deliveries", "error", err)
+	}
+	return result, nil
+}
+
+// post signs and posts a delivery once and records the outcome. A
+// delivery not answered with a 2xx is scheduled again after RetryDelay,
+// until its attempts run out.
+func (s *WebhooksService) post(ctx context.Context, endpoint string, delivery types.Delivery) (*types.Delivery, error) {
+	body, err := json.Marshal(types.Envelope{
+		ID:             delivery.EventID,
+		Type:           delivery.EventType,
+		OrganizationID: delivery.OrganizationID,
+		OccurredAt:     delivery.OccurredAt,
+		Sandbox:        delivery.Sandbox,
+		Data:           delivery.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook delivery: %w", err)
+	}
+	secret, err := s.repo.SubscriptionSecret(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		HeaderEvent:     delivery.EventType,
+		HeaderDelivery:  delivery.ID.String(),
+		HeaderSignature: Sign(secret, now.Unix(), body),
+	}
+
+	postCtx, cancel := context.WithTimeout(ctx, PostTimeout)
+	status, response, postErr := s.sender.Post(postCtx, endpoint, headers, body)
+	cancel()
+
+	attempt := types.Attempt{
+		Status:         types.DeliveryDelivered,
+		At:             now,
+		NextAttemptAt:  now,
+		ResponseStatus: status,
+		ResponseBody:   response,
+	}
+	switch {
+	case postErr != nil:
+		attempt.Error = postErr.Error()
+	case status < 200 || status > 299:
+		attempt.Error = fmt.Sprintf("endpoint answered %d", status)
+	}
+	if attempt.Error != "" {
+		attempt.Status = types.DeliveryPending
+		attempt.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= MaxAttempts || delivery.EventType == types.EventTest {
+			attempt.Status = types.DeliveryFailed
+		}
+	}
+	return s.repo.RecordAttempt(ctx, delivery.ID, attempt)
+}
+
+// RetryDelay is the wait after a delivery's attempts-th failed post
+func RetryDelay(attempts int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Sign returns the signature header of a delivery body posted at the unix
+// time ts: the time and the hex HMAC-SHA256 of "ts.body" under the secret.
+// Receivers recompute it to check the delivery came from us and reject old
+// times to block replays.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+func normalizeSubscription(req *types.SubscriptionRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.URL = strings.TrimSpace(req.URL)
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute https URL", ErrInvalid)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url cannot carry credentials", ErrInvalid)
+	}
+
+	if len(req.EventTypes) == 0 {
+		return fmt.Errorf("%w: event_types is required", ErrInvalid)
+	}
+	seen := make(map[string]bool, len(req.EventTypes))
+	eventTypes := make([]string, 0, len(req.EventTypes))
+	for _, t := range req.EventTypes {
+		t = strings.TrimSpace(t)
+		if !types.IsEventType(t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalid, t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	req.EventTypes = eventTypes
+	return nil
+}
@@ -0,0 +1,208 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types subscriptions can name
+const (
+	EventLeadCreated      = "lead.created"
+	EventLeadStageChanged = "lead.stage_changed"
+	EventLeadWon          = "lead.won"
+	EventLeadLost         = "lead.lost"
+	EventLeadAssigned     = "lead.assigned"
+	// Security events, also sent to the organization's syslog sink
+	EventSecurityUserAdded            = "security.user_added"
+	EventSecurityUserRemoved          = "security.user_removed"
+	EventSecurityRoleChanged          = "security.role_changed"
+	EventSecurityAPIKeyCreated        = "security.api_key_created"
+	EventSecurityExportPerformed      = "security.export_performed"
+	EventSecurityImpersonationStarted = "security.impersonation_started"
+	EventSecurityImpersonationEnded   = "security.impersonation_ended"
+	// EventTest is only sent by testing a subscription
+	EventTest = "webhook.test"
+)
+
+// EventType describes an event subscriptions can name
+type EventType struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// EventTypes are the events subscriptions can name
+var EventTypes = []EventType{
+	{Type: EventLeadCreated, Description: "A lead was created"},
+	{Type: EventLeadStageChanged, Description: "A lead moved to another pipeline stage"},
+	{Type: EventLeadWon, Description: "A lead was closed as won"},
+	{Type: EventLeadLost, Description: "A lead was closed as lost"},
+	{Type: EventLeadAssigned, Description: "A lead was assigned, reassigned or returned to the pool"},
+	{Type: EventSecurityUserAdded, Description: "A user was added to the organization"},
+	{Type: EventSecurityUserRemoved, Description: "A user was removed from the organization"},
+	{Type: EventSecurityRoleChanged, Description: "A member's role was changed"},
+	{Type: EventSecurityAPIKeyCreated, Description: "An API key was created"},
+	{Type: EventSecurityExportPerformed, Description: "An export of the organization's data was taken"},
+	{Type: EventSecurityImpersonationStarted, Description: "An administrator started impersonating a user"},
+	{Type: EventSecurityImpersonationEnded, Description: "An administrator stopped impersonating a user"},
+}
+
+// securityPrefix starts the types of security events
+const securityPrefix = "security."
+
+// IsSecurityEvent reports whether the event type is a security event
+func IsSecurityEvent(eventType string) bool {
+	return strings.HasPrefix(eventType, securityPrefix)
+}
+
+// IsEventType reports whether subscriptions can name the event type
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription posts the events it names to an endpoint. Secret is only
+// filled in when the subscription is created and when it is rotated.
+type Subscription struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"secret,omitempty"`
+	EventTypes     []string   `json:"event_types"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	UpdatedBy      *uuid.UUID `json:"updated_by,omitempty"`
+}
+
+// SubscriptionRequest creates or updates a subscription
+type SubscriptionRequest struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+// DeliveryStatus is where a delivery stands
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// IsValid reports whether the status is known
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DeliveryPending, DeliveryDelivered, DeliveryFailed:
+		return true
+	}
+	return false
+}
+
+// Delivery is one event posted, or to be posted, to one subscription
+type Delivery struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Sandbox        bool            `json:"sandbox"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// DeliveryFilter narrows a delivery log
+type DeliveryFilter struct {
+	OrganizationID uuid.UUID
+	SubscriptionID *uuid.UUID
+	EventType      string
+	Status         DeliveryStatus
+	Limit          int
+	Offset         int
+}
+
+// Attempt is the outcome of posting a delivery once
+type Attempt struct {
+	Status         DeliveryStatus
+	At             time.Time
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	ResponseBody   string
+	Error          string
+}
+
+// Envelope is the body posted to subscriptions
+type Envelope struct {
+	ID             uuid.UUID       `json:"id"`
+	Type           string          `json:"type"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Sandbox        bool            `json:"sandbox"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// DispatchResult counts the deliveries of a dispatch pass
+type DispatchResult struct {
+	Delivered int `json:"delivered"`
+	Retrying  int `json:"retrying"`
+	Failed    int `json:"failed"`
+}
+
+// SyslogProtocol is how security events reach a syslog sink
+type SyslogProtocol string
+
+const (
+	SyslogUDP SyslogProtocol = "udp"
+	SyslogTCP SyslogProtocol = "tcp"
+	SyslogTLS SyslogProtocol = "tls"
+)
+
+// IsValid reports whether the protocol is known
+func (p SyslogProtocol) IsValid() bool {
+	switch p {
+	case SyslogUDP, SyslogTCP, SyslogTLS:
+		return true
+	}
+	return false
+}
+
+// SyslogSink sends the organization's security events to its SIEM's syslog
+// collector. Sends are not retried; the outcome of the last one is kept.
+type SyslogSink struct {
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	Address        string         `json:"address"`
+	Protocol       SyslogProtocol `json:"protocol"`
+	Active         bool           `json:"active"`
+	LastSentAt     *time.Time     `json:"last_sent_at,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	UpdatedBy      *uuid.UUID     `json:"updated_by,omitempty"`
+}
+
+// SyslogSinkRequest configures the organization's syslog sink
+type SyslogSinkRequest struct {
+	// Address is the collector's host:port
+	Address  string         `json:"address"`
+	Protocol SyslogProtocol `json:"protocol"`
+	Active   *bool          `json:"active,omitempty"`
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+	"github.com/KevTiv/alieze-erp/pkg/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultRateLimit is the per-minute request allowance for production organizations
+	defaultRateLimit = 600
+	// defaultSandboxRateLimit is the relaxed allowance for sandbox organizations,
+	// sized for integrators replaying test suites
+	defaultSandboxRateLimit = 6000
+)
+
+// rateLimitConfig holds the per-minute request allowances
+type rateLimitConfig struct {
+	limiter      *ratelimit.Limiter
+	perMinute    int
+	sandboxLimit int
+}
+
+func newRateLimitConfig() *rateLimitConfig {
+	return &rateLimitConfig{
+		limiter:      ratelimit.NewLimiter(time.Minute),
+		perMinute:    envInt("RATE_LIMIT_PER_MINUTE", defaultRateLimit),
+		sandboxLimit: envInt("SANDBOX_RATE_LIMIT_PER_MINUTE", defaultSandboxRateLimit),
+	}
+}
+
+// rateLimitMiddleware limits requests per organization, or per client IP for
+// unauthenticated routes. It must run inside the auth middleware.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.rateLimit == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, limit := "ip:"+clientIP(r), s.rateLimit.perMinute
+		if authCtx, err := auth.FromContext(r.Context()); err == nil {
+			key = "org:" + authCtx.OrganizationID.String()
+			if authCtx.IsSandbox {
+				limit = s.rateLimit.sandboxLimit
+			} else if planLimit := s.planRateLimit(r, authCtx.OrganizationID); planLimit > 0 {
+				limit = planLimit
+			}
+		}
+
+		// Every response advertises the allowance, so integrators can
+		// throttle themselves before they are refused
+		res := s.rateLimit.limiter.Allow(key, limit)
+		ratelimit.SetHeaders(w.Header(), res)
+		if !res.Allowed {
+			retryAfter := int(time.Until(res.ResetAt).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ratelimit.WithResult(r.Context(), res)))
+	})
+}
+
+// planRateLimit returns the allowance of the organization's plan, 0 when
+// there is none
+func (s *Server) planRateLimit(r *http.Request, orgID uuid.UUID) int {
+	if s.entitlements == nil || s.entitlements.EntitlementsService() == nil {
+		return 0
+	}
+	return s.entitlements.EntitlementsService().RateLimit(r.Context(), orgID)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func envInt(name string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/KevTiv/alieze-erp/pkg/apiversion"
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -18,8 +21,40 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	r.HandlerFunc(http.MethodGet, "/health", s.healthHandler)
 
+	// List the enumerations modules declare, so clients need not hardcode them
+	enumHandler, err := enums.NewHandler(s.registry.Enums())
+	if err != nil {
+		log.Fatalf("error declaring enumerations. Err: %v", err)
+	}
+	r.Handler(http.MethodGet, "/api/v1/metadata/enums", enumHandler)
+
+	// List the versioned endpoints with their deprecations, sunsets and recent use
+	r.Handler(http.MethodGet, "/api/v1/metadata/api-versions", s.apiVersions)
+
+	// Serve locally stored files to holders of a signed download URL
+	if s.signedFiles != nil {
+		r.Handler(http.MethodGet, "/public/files/*key", s.signedFiles)
+	}
+
+	// Serve the API version asked for in the API-Version header where the endpoint has it
+	versionWrapper := apiversion.Negotiate(r, r)
+
+	// Meter the API calls and active users of each organization for billing
+	meteringWrapper := s.metering.Middleware(versionWrapper)
+
+	// Limit request rates per organization; sandbox organizations get a relaxed allowance
+	rateLimitWrapper := s.rateLimitMiddleware(meteringWrapper)
+
+	// Log failed requests and a sample of the others for platform administrators,
+	// who can replay failed ones in sandboxes through the router
+	s.requestLog.SetReplayHandler(r)
+	requestLogWrapper := s.requestLog.Middleware(rateLimitWrapper)
+
+	// Keep partner-role users on the partner API
+	partnerScopeWrapper := s.partners.Middleware(requestLogWrapper)
+
 	// Wrap all routes with CORS middleware
-	corsWrapper := s.corsMiddleware(r)
+	corsWrapper := s.corsMiddleware(partnerScopeWrapper)
 
 	// Wrap with auth middleware (after CORS)
 	authWrapper := s.authModule.GetMiddleware().Middleware(corsWrapper)
@@ -35,6 +70,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
 		w.Header().Set("Access-Control-Allow-Credentials", "false") // Set to "true" if credentials are needed
+		// Browser clients may read the rate limit headers to throttle themselves
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		// Handle preflight OPTIONS requests
 		if r.Method == http.MethodOptions {
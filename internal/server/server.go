@@ -12,7 +12,10 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"github.com/KevTiv/alieze-erp/internal/database"
+	"github.com/KevTiv/alieze-erp/internal/database/selfcheck"
+	"github.com/KevTiv/alieze-erp/pkg/apiversion"
 	"github.com/KevTiv/alieze-erp/pkg/audit"
+	"github.com/KevTiv/alieze-erp/pkg/cache"
 	authmodule "github.com/KevTiv/alieze-erp/internal/modules/auth"
 	commonmodule "github.com/KevTiv/alieze-erp/internal/modules/common"
 	crmmodule "github.com/KevTiv/alieze-erp/internal/modules/crm"
@@ -20,11 +23,53 @@ import (
 	inventorymodule "github.com/KevTiv/alieze-erp/internal/modules/inventory"
 	productsmodule "github.com/KevTiv/alieze-erp/internal/modules/products"
 	salesmodule "github.com/KevTiv/alieze-erp/internal/modules/sales"
+	searchmodule "github.com/KevTiv/alieze-erp/internal/modules/search"
+	retentionmodule "github.com/KevTiv/alieze-erp/internal/modules/retention"
+	sandboxmodule "github.com/KevTiv/alieze-erp/internal/modules/sandbox"
+	calendarmodule "github.com/KevTiv/alieze-erp/internal/modules/calendar"
+	escalationmodule "github.com/KevTiv/alieze-erp/internal/modules/escalation"
+	customentitymodule "github.com/KevTiv/alieze-erp/internal/modules/customentity"
+	computedfieldsmodule "github.com/KevTiv/alieze-erp/internal/modules/computedfields"
+	customfieldsmodule "github.com/KevTiv/alieze-erp/internal/modules/customfields"
+	statuspagemodule "github.com/KevTiv/alieze-erp/internal/modules/statuspage"
+	documentsmodule "github.com/KevTiv/alieze-erp/internal/modules/documents"
+	edimodule "github.com/KevTiv/alieze-erp/internal/modules/edi"
+	commissionmodule "github.com/KevTiv/alieze-erp/internal/modules/commission"
+	collectionsmodule "github.com/KevTiv/alieze-erp/internal/modules/collections"
+	collectionsmailer "github.com/KevTiv/alieze-erp/internal/modules/collections/mailer"
+	budgetmodule "github.com/KevTiv/alieze-erp/internal/modules/budget"
+	assetsmodule "github.com/KevTiv/alieze-erp/internal/modules/assets"
+	onboardingmodule "github.com/KevTiv/alieze-erp/internal/modules/onboarding"
+	recruitmentmodule "github.com/KevTiv/alieze-erp/internal/modules/recruitment"
+	performancemodule "github.com/KevTiv/alieze-erp/internal/modules/performance"
+	trainingmodule "github.com/KevTiv/alieze-erp/internal/modules/training"
+	calibrationmodule "github.com/KevTiv/alieze-erp/internal/modules/calibration"
+	visitorsmodule "github.com/KevTiv/alieze-erp/internal/modules/visitors"
+	compliancemodule "github.com/KevTiv/alieze-erp/internal/modules/compliance"
+	offboardingmodule "github.com/KevTiv/alieze-erp/internal/modules/offboarding"
+	portalmodule "github.com/KevTiv/alieze-erp/internal/modules/portal"
+	documenttypes "github.com/KevTiv/alieze-erp/internal/modules/documents/types"
 	deliverymodule "github.com/KevTiv/alieze-erp/internal/modules/delivery"
+	emaildomainsmodule "github.com/KevTiv/alieze-erp/internal/modules/emaildomains"
+	exportsmodule "github.com/KevTiv/alieze-erp/internal/modules/exports"
+	requestlogmodule "github.com/KevTiv/alieze-erp/internal/modules/requestlog"
+	dataqualitymodule "github.com/KevTiv/alieze-erp/internal/modules/dataquality"
+	meteringmodule "github.com/KevTiv/alieze-erp/internal/modules/metering"
+	entitlementsmodule "github.com/KevTiv/alieze-erp/internal/modules/entitlements"
+	surveysmodule "github.com/KevTiv/alieze-erp/internal/modules/surveys"
+	surveyssms "github.com/KevTiv/alieze-erp/internal/modules/surveys/sms"
+	partnersmodule "github.com/KevTiv/alieze-erp/internal/modules/partners"
+	webhooksmodule "github.com/KevTiv/alieze-erp/internal/modules/webhooks"
+	contractsmodule "github.com/KevTiv/alieze-erp/internal/modules/contracts"
+	"github.com/KevTiv/alieze-erp/pkg/email"
 	"github.com/KevTiv/alieze-erp/pkg/events"
 	"github.com/KevTiv/alieze-erp/pkg/policy"
+	"github.com/KevTiv/alieze-erp/pkg/push"
 	"github.com/KevTiv/alieze-erp/pkg/registry"
 	"github.com/KevTiv/alieze-erp/pkg/rules"
+	sqldialect "github.com/KevTiv/alieze-erp/pkg/database"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
+	"github.com/KevTiv/alieze-erp/pkg/storage"
 	"github.com/KevTiv/alieze-erp/pkg/workflow"
 )
 
@@ -39,6 +84,13 @@ type Server struct {
 	policyEngine     *policy.Engine
 	stateMachineFactory *workflow.StateMachineFactory
 	logger           *slog.Logger
+	rateLimit        *rateLimitConfig
+	requestLog       *requestlogmodule.RequestLogModule
+	metering         *meteringmodule.MeteringModule
+	entitlements     *entitlementsmodule.EntitlementsModule
+	partners         *partnersmodule.PartnersModule
+	signedFiles      http.Handler
+	apiVersions      *apiversion.Catalog
 }
 
 func NewServer() *http.Server {
@@ -50,6 +102,45 @@ func NewServer() *http.Server {
 	// Initialize database
 	dbService := database.New()
 
+	// Repositories render SQL for DB_DIALECT; only PostgreSQL wire
+	// compatible databases can be reached through the pgx driver
+	dialect, err := sqldialect.ParseDialect(os.Getenv("DB_DIALECT"))
+	if err == nil && dialect == sqldialect.MySQL {
+		err = fmt.Errorf("database dialect %s has no driver in this build", dialect.Name())
+	}
+	if err != nil {
+		logger.Error("Invalid database dialect", "error", err)
+		os.Exit(1)
+	}
+
+	// Check the schema and settings before wiring the modules, so a missing
+	// migration, relation or setting stops the boot with a fix instead of
+	// failing requests later
+	selfCheckMode, err := selfcheck.ParseMode(os.Getenv("STARTUP_SELF_CHECK"))
+	if err != nil {
+		logger.Error("Invalid startup self-check mode", "error", err)
+		os.Exit(1)
+	}
+	if selfCheckMode != selfcheck.ModeOff {
+		latestMigration, err := database.LatestMigrationVersion()
+		if err != nil {
+			logger.Error("Failed to read built-in migrations", "error", err)
+			os.Exit(1)
+		}
+		checkCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = selfcheck.Run(checkCtx, dbService.GetDB(), dialect, selfcheck.Defaults(latestMigration), os.Getenv)
+		cancel()
+		switch {
+		case err == nil:
+			logger.Info("Startup self-check passed", "migration", latestMigration)
+		case selfCheckMode == selfcheck.ModeEnforce:
+			logger.Error("Startup self-check failed; set STARTUP_SELF_CHECK=warn to start anyway", "error", err)
+			os.Exit(1)
+		default:
+			logger.Warn("Startup self-check failed, starting anyway", "error", err)
+		}
+	}
+
 	// Initialize core infrastructure
 	eventBus := events.NewBus(false) // Use synchronous event processing for now
 
@@ -84,7 +175,7 @@ func NewServer() *http.Server {
 	auditLogger := audit.NewAuditLogger(auditRepo)
 
 	// Create permission-aware database connection with audit logging
-	permissionDB := database.NewPermissionDB(dbService.GetDB(), policyEngine, auditLogger)
+	permissionDB := sqldialect.NewPermissionDB(dbService.GetDB(), policyEngine, auditLogger)
 
 	// Initialize state machine factory
 	stateMachineFactory := workflow.NewStateMachineFactory()
@@ -93,13 +184,30 @@ func NewServer() *http.Server {
 		// Continue without workflows - they're optional for now
 	}
 
+	// Coordinate scheduled jobs so each runs once across all instances.
+	// Leader election needs advisory locks; without them every instance
+	// runs every job.
+	var jobScheduler *scheduler.Coordinator
+	if dialect.SupportsAdvisoryLocks() {
+		jobScheduler = scheduler.NewCoordinator(dbService.GetDB(), logger.With("component", "scheduler"))
+	} else {
+		logger.Warn("Scheduled jobs are not coordinated across instances", "dialect", dialect.Name())
+	}
+
+	// Modules register v1 and v2 of an endpoint side by side; the catalog lists them with their deprecations and sunsets
+	apiVersions := apiversion.NewCatalog()
+
 	// Initialize base dependencies
 	baseDeps := registry.Dependencies{
-		DB:                  permissionDB,
+		DB:                  dbService.GetDB(),
+		PermissionDB:        permissionDB,
+		Dialect:             dialect,
 		EventBus:            eventBus,
 		RuleEngine:          ruleEngine,
 		PolicyEngine:        policyEngine,
 		StateMachineFactory: stateMachineFactory,
+		Scheduler:           jobScheduler,
+		APIVersions:         apiVersions,
 		Logger:              logger,
 	}
 
@@ -115,6 +223,40 @@ func NewServer() *http.Server {
 	productsMod := productsmodule.NewProductsModule()
 	salesMod := salesmodule.NewSalesModule()
 	deliveryMod := deliverymodule.NewDeliveryModule()
+	searchMod := searchmodule.NewSearchModule()
+	retentionMod := retentionmodule.NewRetentionModule()
+	sandboxMod := sandboxmodule.NewSandboxModule()
+	calendarMod := calendarmodule.NewCalendarModule()
+	escalationMod := escalationmodule.NewEscalationModule()
+	customEntityMod := customentitymodule.NewCustomEntityModule()
+	computedFieldsMod := computedfieldsmodule.NewComputedFieldsModule()
+	customFieldsMod := customfieldsmodule.NewCustomFieldsModule()
+	statusPageMod := statuspagemodule.NewStatusPageModule()
+	documentsMod := documentsmodule.NewDocumentsModule()
+	ediMod := edimodule.NewEDIModule()
+	commissionMod := commissionmodule.NewCommissionModule()
+	collectionsMod := collectionsmodule.NewCollectionsModule()
+	budgetMod := budgetmodule.NewBudgetModule()
+	assetsMod := assetsmodule.NewAssetsModule()
+	onboardingMod := onboardingmodule.NewOnboardingModule()
+	recruitmentMod := recruitmentmodule.NewRecruitmentModule()
+	performanceMod := performancemodule.NewPerformanceModule()
+	trainingMod := trainingmodule.NewTrainingModule()
+	calibrationMod := calibrationmodule.NewCalibrationModule()
+	visitorsMod := visitorsmodule.NewVisitorsModule()
+	complianceMod := compliancemodule.NewComplianceModule()
+	offboardingMod := offboardingmodule.NewOffboardingModule()
+	portalMod := portalmodule.NewPortalModule()
+	emailDomainsMod := emaildomainsmodule.NewEmailDomainsModule()
+	exportsMod := exportsmodule.NewExportsModule()
+	requestLogMod := requestlogmodule.NewRequestLogModule()
+	dataQualityMod := dataqualitymodule.NewDataQualityModule()
+	meteringMod := meteringmodule.NewMeteringModule()
+	entitlementsMod := entitlementsmodule.NewEntitlementsModule()
+	surveysMod := surveysmodule.NewSurveysModule()
+	partnersMod := partnersmodule.NewPartnersModule()
+	webhooksMod := webhooksmodule.NewWebhooksModule()
+	contractsMod := contractsmodule.NewContractsModule()
 
 	repoRegistry.Register(authMod)
 	repoRegistry.Register(commonMod)
@@ -124,9 +266,47 @@ func NewServer() *http.Server {
 	repoRegistry.Register(productsMod)
 	repoRegistry.Register(salesMod)
 	repoRegistry.Register(deliveryMod)
+	repoRegistry.Register(searchMod)
+	repoRegistry.Register(retentionMod)
+	repoRegistry.Register(sandboxMod)
+	repoRegistry.Register(calendarMod)
+	repoRegistry.Register(escalationMod)
+	repoRegistry.Register(customEntityMod)
+	repoRegistry.Register(computedFieldsMod)
+	repoRegistry.Register(customFieldsMod)
+	repoRegistry.Register(statusPageMod)
+	repoRegistry.Register(documentsMod)
+	repoRegistry.Register(ediMod)
+	repoRegistry.Register(commissionMod)
+	repoRegistry.Register(collectionsMod)
+	repoRegistry.Register(budgetMod)
+	repoRegistry.Register(assetsMod)
+	repoRegistry.Register(onboardingMod)
+	repoRegistry.Register(recruitmentMod)
+	repoRegistry.Register(performanceMod)
+	repoRegistry.Register(trainingMod)
+	repoRegistry.Register(calibrationMod)
+	repoRegistry.Register(visitorsMod)
+	repoRegistry.Register(complianceMod)
+	repoRegistry.Register(offboardingMod)
+	repoRegistry.Register(portalMod)
+	repoRegistry.Register(emailDomainsMod)
+	repoRegistry.Register(exportsMod)
+	repoRegistry.Register(requestLogMod)
+	repoRegistry.Register(dataQualityMod)
+	repoRegistry.Register(meteringMod)
+	repoRegistry.Register(entitlementsMod)
+	repoRegistry.Register(surveysMod)
+	repoRegistry.Register(partnersMod)
+	repoRegistry.Register(webhooksMod)
+	repoRegistry.Register(contractsMod)
 
-	// Phase 1: Initialize auth, common, and products modules first (needed by inventory)
 	ctx := context.Background()
+
+	// Campaign for scheduler leadership; module jobs only run on the leader
+	jobScheduler.Start(ctx)
+
+	// Phase 1: Initialize auth, common, and products modules first (needed by inventory)
 	if err := authMod.Init(ctx, baseDeps); err != nil {
 		logger.Error("Failed to initialize auth module", "error", err)
 		os.Exit(1)
@@ -174,6 +354,322 @@ func NewServer() *http.Server {
 		logger.Error("Failed to initialize delivery module", "error", err)
 		os.Exit(1)
 	}
+	if err := searchMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize search module", "error", err)
+		os.Exit(1)
+	}
+	if err := retentionMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize retention module", "error", err)
+		os.Exit(1)
+	}
+	if err := sandboxMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize sandbox module", "error", err)
+		os.Exit(1)
+	}
+	if err := calendarMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize calendar module", "error", err)
+		os.Exit(1)
+	}
+	if err := escalationMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize escalation module", "error", err)
+		os.Exit(1)
+	}
+	if err := customEntityMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize custom entity module", "error", err)
+		os.Exit(1)
+	}
+	if err := computedFieldsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize computed fields module", "error", err)
+		os.Exit(1)
+	}
+	if err := customFieldsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize custom fields module", "error", err)
+		os.Exit(1)
+	}
+	if err := statusPageMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize status page module", "error", err)
+		os.Exit(1)
+	}
+	if err := documentsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize documents module", "error", err)
+		os.Exit(1)
+	}
+	if err := ediMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize EDI module", "error", err)
+		os.Exit(1)
+	}
+	if err := commissionMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize commission module", "error", err)
+		os.Exit(1)
+	}
+	if err := collectionsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize collections module", "error", err)
+		os.Exit(1)
+	}
+	if err := budgetMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize budget module", "error", err)
+		os.Exit(1)
+	}
+	if err := assetsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize assets module", "error", err)
+		os.Exit(1)
+	}
+	if err := onboardingMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize onboarding module", "error", err)
+		os.Exit(1)
+	}
+	if err := recruitmentMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize recruitment module", "error", err)
+		os.Exit(1)
+	}
+	if err := performanceMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize performance module", "error", err)
+		os.Exit(1)
+	}
+	if err := trainingMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize training module", "error", err)
+		os.Exit(1)
+	}
+	if err := calibrationMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize calibration module", "error", err)
+		os.Exit(1)
+	}
+	if err := visitorsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize visitors module", "error", err)
+		os.Exit(1)
+	}
+	if err := complianceMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize compliance module", "error", err)
+		os.Exit(1)
+	}
+	if err := offboardingMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize offboarding module", "error", err)
+		os.Exit(1)
+	}
+	if err := portalMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize portal module", "error", err)
+		os.Exit(1)
+	}
+	if err := emailDomainsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize email domains module", "error", err)
+		os.Exit(1)
+	}
+	if err := exportsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize exports module", "error", err)
+		os.Exit(1)
+	}
+	if err := requestLogMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize request log module", "error", err)
+		os.Exit(1)
+	}
+	if err := dataQualityMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize data quality module", "error", err)
+		os.Exit(1)
+	}
+	if err := meteringMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize metering module", "error", err)
+		os.Exit(1)
+	}
+	if err := entitlementsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize entitlements module", "error", err)
+		os.Exit(1)
+	}
+	if err := surveysMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize surveys module", "error", err)
+		os.Exit(1)
+	}
+	if err := partnersMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize partners module", "error", err)
+		os.Exit(1)
+	}
+	if err := webhooksMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize webhooks module", "error", err)
+		os.Exit(1)
+	}
+	if err := contractsMod.Init(ctx, baseDeps); err != nil {
+		logger.Error("Failed to initialize contracts module", "error", err)
+		os.Exit(1)
+	}
+
+	// Route manifests can also be printed with organization-branded document templates
+	documentsMod.DocumentService().RegisterDataSource(documenttypes.DocumentKindRouteManifest, deliveryMod.GetManifestService())
+
+	// Visitor badges are printed from document templates
+	documentsMod.DocumentService().RegisterDataSource(documenttypes.DocumentKindVisitorBadge, visitorsMod.VisitorService())
+
+	// Invoices and proofs of delivery are printed from document templates, one at a time or
+	// in batches selecting e.g. all invoices of a month or all deliveries of a route
+	documentsMod.DocumentService().RegisterDataSource(documenttypes.DocumentKindInvoice, portalMod.InvoiceSource())
+	documentsMod.DocumentService().RegisterDataSource(documenttypes.DocumentKindProofOfDelivery, deliveryMod.GetProofService())
+
+	// Customers download their invoices printed with the organization's invoice template
+	portalMod.SetRenderer(documentsMod.DocumentService())
+
+	// QC inspections and hazmat routes are only assigned to employees holding the required certifications
+	inventoryMod.SetInspectorQualifier(trainingMod.TrainingService())
+	deliveryMod.SetDriverQualifier(trainingMod.TrainingService())
+
+	// QC inspection items can only record measuring equipment that is in calibration
+	inventoryMod.SetInstrumentChecker(calibrationMod.CalibrationService())
+
+	// Sign-ins are recorded for the audit trail, which data retention policies never purge before its own retention
+	authMod.SetLoginRecorder(complianceMod.ComplianceService())
+	retentionMod.SetAuditTrailRetention(complianceMod.ComplianceService())
+
+	// Tenant export archives, document batch archives and lead attachments are kept in, and a leaving tenant's attachment files deleted from, the configured file storage.
+	// Local files are downloaded through signed URLs under /public/files
+	var signedFiles http.Handler
+	fileStorage, err := storage.NewStorage(&storage.Config{
+		Provider: os.Getenv("STORAGE_PROVIDER"),
+		Local: &storage.LocalConfig{
+			BasePath:   os.Getenv("STORAGE_LOCAL_PATH"),
+			URLPrefix:  os.Getenv("API_PUBLIC_URL") + "/public/files",
+			SigningKey: os.Getenv("STORAGE_SIGNING_KEY"),
+		},
+		S3: &storage.S3Config{
+			Region:         os.Getenv("STORAGE_S3_REGION"),
+			Bucket:         os.Getenv("STORAGE_S3_BUCKET"),
+			Endpoint:       os.Getenv("STORAGE_S3_ENDPOINT"),
+			AccessKey:      os.Getenv("STORAGE_S3_ACCESS_KEY"),
+			SecretKey:      os.Getenv("STORAGE_S3_SECRET_KEY"),
+			UseSSL:         os.Getenv("STORAGE_S3_USE_SSL") == "true",
+			ForcePathStyle: os.Getenv("STORAGE_S3_FORCE_PATH_STYLE") == "true",
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to configure file storage; tenant exports, deletions, packaged document batches and attachments are unavailable", "error", err)
+	} else {
+		commonMod.SetStorage(fileStorage)
+		offboardingMod.SetStorage(fileStorage)
+		documentsMod.SetStorage(fileStorage)
+		crmMod.SetStorage(fileStorage, os.Getenv("STORAGE_PROVIDER"))
+		if local, ok := fileStorage.(*storage.LocalStorage); ok {
+			signedFiles = http.StripPrefix("/public/files", local.ServeSigned())
+		}
+	}
+
+	// Deletion certificates are signed so that whoever they are handed to can check them against tampering
+	if signingKey := os.Getenv("OFFBOARDING_SIGNING_KEY"); signingKey != "" {
+		offboardingMod.SetSigningKey([]byte(signingKey))
+	} else {
+		logger.Info("OFFBOARDING_SIGNING_KEY not set; deletion certificates carry a digest but no signature")
+	}
+
+	// Passwords and keys of export destinations are stored encrypted
+	if credentialKey := os.Getenv("EXPORT_CREDENTIALS_KEY"); credentialKey != "" {
+		exportsMod.SetCredentialKey(credentialKey)
+	} else {
+		logger.Info("EXPORT_CREDENTIALS_KEY not set; export destinations needing credentials cannot be saved or used")
+	}
+
+	// Driver and dispatcher messages, visitor arrivals for their hosts, finished document batches for their requesters and
+	// rotated leads for their team leaders are pushed to devices through the push relay when one is configured
+	if relayURL := os.Getenv("PUSH_RELAY_URL"); relayURL != "" {
+		notifier := push.NewRelayNotifier(relayURL, os.Getenv("PUSH_RELAY_TOKEN"))
+		deliveryMod.SetPushNotifier(notifier)
+		visitorsMod.SetPushNotifier(notifier)
+		documentsMod.SetPushNotifier(notifier)
+		crmMod.SetPushNotifier(notifier)
+	} else {
+		logger.Info("PUSH_RELAY_URL not set; route messages, visitor arrivals, finished document batches and rotated leads are not pushed to devices")
+	}
+
+	// The by-<filter> lead routes are deprecated in favour of GET /api/v1/leads query parameters
+	if os.Getenv("CRM_LEGACY_LEAD_FILTER_ROUTES") == "false" {
+		crmMod.SetLegacyLeadFilterRoutes(false)
+	} else {
+		logger.Info("Deprecated /api/v1/leads/by-<filter> routes are served; set CRM_LEGACY_LEAD_FILTER_ROUTES=false to remove them")
+	}
+
+	// Lead lists count the matching leads on every page unless turned off
+	// for tables too large to count
+	if os.Getenv("CRM_LEAD_LIST_TOTALS") == "false" {
+		crmMod.SetLeadListTotals(false)
+	}
+
+	// CRM and activity dashboards are cached in Redis when one is configured, so that every instance shares them and
+	// sees their invalidations; otherwise each instance caches them in its own memory
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		redisOpts, err := cache.ParseRedisURL(redisURL)
+		if err != nil {
+			logger.Error("Invalid REDIS_URL; dashboards are cached in each instance's memory", "error", err)
+		} else {
+			redisOpts.Prefix = os.Getenv("REDIS_KEY_PREFIX")
+			crmMod.SetDashboardStore(cache.NewRedis(redisOpts))
+		}
+	} else {
+		logger.Info("REDIS_URL not set; dashboards are cached in each instance's memory")
+	}
+
+	// Dunning notices are not emailed to customers who opted out of email
+	collectionsMod.SetConsentChecker(crmMod.ContactConsentService())
+
+	// Survey invitations are not sent to customers who opted out of the survey's channel
+	surveysMod.SetConsentChecker(crmMod.ContactConsentService())
+
+	// Leads, active assignment rules and sandboxes are limited by the organization's plan
+	crmMod.SetEntitlements(entitlementsMod.EntitlementsService())
+	sandboxMod.SetEntitlements(entitlementsMod.EntitlementsService())
+
+	// Quotas report the organization's metered usage this month
+	entitlementsMod.SetUsageReader(meteringMod.UsageReader())
+
+	// Dunning notices, portal sign-in links and opt-in confirmations are emailed to customers, and failed exports to
+	// their alert addresses, when an SMTP server is configured
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		mailer, err := collectionsmailer.NewSMTPMailer(&email.SMTPConfig{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			TLS:      os.Getenv("SMTP_TLS") != "false",
+		}, os.Getenv("EMAIL_FROM"))
+		if err != nil {
+			logger.Error("Failed to configure dunning email", "error", err)
+		} else {
+			// Emails sent are metered against the organization they are sent for
+			meteredMailer := meteringMod.WrapMailer(mailer)
+			collectionsMod.SetMailer(meteredMailer)
+			portalMod.SetMailer(meteredMailer)
+			exportsMod.SetMailer(meteredMailer)
+			crmMod.SetMailer(meteredMailer)
+			surveysMod.SetMailer(meteredMailer)
+			partnersMod.SetMailer(meteredMailer)
+			contractsMod.SetMailer(meteredMailer)
+		}
+	} else {
+		logger.Info("SMTP_HOST not set; dunning notices are recorded but not emailed, portal customers can only sign in with a password, failed exports are not alerted by email, email opt-ins cannot be double opt-in, email survey invitations are skipped, partners are not emailed deal registration decisions, and contract expiry reminders are skipped")
+	}
+
+	// SMS survey invitations are texted through the SMS gateway when one is configured
+	if gatewayURL := os.Getenv("SMS_GATEWAY_URL"); gatewayURL != "" {
+		surveysMod.SetSMSSender(surveyssms.NewGatewaySender(gatewayURL, os.Getenv("SMS_GATEWAY_TOKEN")))
+	} else {
+		logger.Info("SMS_GATEWAY_URL not set; SMS survey invitations are skipped")
+	}
+
+	// Portal sign-in links open the customer portal's sign-in page
+	if portalURL := os.Getenv("PORTAL_URL"); portalURL != "" {
+		portalMod.SetPortalURL(portalURL)
+	} else {
+		logger.Info("PORTAL_URL not set; portal sign-in links are not emailed")
+	}
+
+	// Opt-in confirmation and survey links open the API's public routes
+	if publicURL := os.Getenv("API_PUBLIC_URL"); publicURL != "" {
+		crmMod.SetPublicURL(publicURL)
+		surveysMod.SetPublicURL(publicURL)
+	} else {
+		logger.Info("API_PUBLIC_URL not set; email opt-ins cannot be double opt-in and survey invitations are skipped")
+	}
+
+	// Organizations authorize the platform's mail servers and point tracking domains at the platform's tracking host
+	spfInclude, trackingTarget := os.Getenv("EMAIL_SPF_INCLUDE"), os.Getenv("EMAIL_TRACKING_CNAME")
+	if spfInclude == "" || trackingTarget == "" {
+		logger.Info("EMAIL_SPF_INCLUDE or EMAIL_TRACKING_CNAME not set; email domains are verified against the default SPF include and tracking host")
+	}
+	emailDomainsMod.SetDNSTargets(spfInclude, trackingTarget)
 
 	// Register event handlers for all modules
 	repoRegistry.RegisterAllEventHandlers(eventBus)
@@ -189,6 +685,13 @@ func NewServer() *http.Server {
 		policyEngine:      policyEngine,
 		stateMachineFactory: stateMachineFactory,
 		logger:            logger,
+		rateLimit:         newRateLimitConfig(),
+		requestLog:        requestLogMod,
+		metering:          meteringMod,
+		entitlements:      entitlementsMod,
+		partners:          partnersMod,
+		signedFiles:       signedFiles,
+		apiVersions:       apiVersions,
 	}
 
 	// Declare Server config
@@ -39,6 +39,10 @@ type MockAssignmentRuleRepository struct {
 	updateUserAssignmentLoadFunc       func(ctx context.Context, load *types.UserAssignmentLoad) error
 	listUserAssignmentLoadsFunc        func(ctx context.Context, orgID uuid.UUID, targetModel string) ([]*types.UserAssignmentLoad, error)
 	listAssignmentHistoryFunc          func(ctx context.Context, orgID uuid.UUID, targetModel string, limit int) ([]*types.AssignmentHistory, error)
+	findMatchingRuleFunc               func(ctx context.Context, orgID uuid.UUID, targetModel string, conditions map[string]interface{}) (*types.AssignmentRule, error)
+	listCandidateLoadsFunc             func(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error)
+	getUserNameFunc                    func(ctx context.Context, userID uuid.UUID) (string, error)
+	recordAssignmentFunc               func(ctx context.Context, record types.AssignmentRecord) error
 }
 
 // NewMockAssignmentRuleRepository creates a new mock assignment rule repository
@@ -283,6 +287,50 @@ func (m *MockAssignmentRuleRepository) GetNextAssignee(ctx context.Context, targ
 	return uuid.Must(uuid.NewV7()), "Test User", nil
 }
 
+// FindMatchingRule implements the repository interface
+func (m *MockAssignmentRuleRepository) FindMatchingRule(ctx context.Context, orgID uuid.UUID, targetModel string, conditions map[string]interface{}) (*types.AssignmentRule, error) {
+	if m.findMatchingRuleFunc != nil {
+		return m.findMatchingRuleFunc(ctx, orgID, targetModel, conditions)
+	}
+	return &types.AssignmentRule{
+		ID:               uuid.Must(uuid.NewV7()),
+		OrganizationID:   orgID,
+		Name:             "Test Rule",
+		RuleType:         types.AssignmentRuleTypeRoundRobin,
+		TargetModel:      types.AssignmentTargetModel(targetModel),
+		IsActive:         true,
+		AssignmentConfig: types.AssignmentConfig{Users: []uuid.UUID{uuid.Must(uuid.NewV7())}},
+	}, nil
+}
+
+// ListCandidateLoads implements the repository interface
+func (m *MockAssignmentRuleRepository) ListCandidateLoads(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error) {
+	if m.listCandidateLoadsFunc != nil {
+		return m.listCandidateLoadsFunc(ctx, orgID, targetModel, userIDs)
+	}
+	loads := make([]types.UserAssignmentLoad, len(userIDs))
+	for i, userID := range userIDs {
+		loads[i] = types.UserAssignmentLoad{OrganizationID: orgID, UserID: userID, TargetModel: targetModel, Weight: 1, IsAvailable: true}
+	}
+	return loads, nil
+}
+
+// GetUserName implements the repository interface
+func (m *MockAssignmentRuleRepository) GetUserName(ctx context.Context, userID uuid.UUID) (string, error) {
+	if m.getUserNameFunc != nil {
+		return m.getUserNameFunc(ctx, userID)
+	}
+	return "Test User", nil
+}
+
+// RecordAssignment implements the repository interface
+func (m *MockAssignmentRuleRepository) RecordAssignment(ctx context.Context, record types.AssignmentRecord) error {
+	if m.recordAssignmentFunc != nil {
+		return m.recordAssignmentFunc(ctx, record)
+	}
+	return nil
+}
+
 // getLead is a helper function for testing
 func (m *MockAssignmentRuleRepository) GetLead(ctx context.Context, id uuid.UUID) (*types.Lead, error) {
 	if m.getLeadFunc != nil {
@@ -551,3 +599,23 @@ func (m *MockAssignmentRuleRepository) WithFindByTargetModelFunc(f func(ctx cont
 	m.findByTargetModelFunc = f
 	return m
 }
+
+func (m *MockAssignmentRuleRepository) WithFindMatchingRuleFunc(f func(ctx context.Context, orgID uuid.UUID, targetModel string, conditions map[string]interface{}) (*types.AssignmentRule, error)) *MockAssignmentRuleRepository {
+	m.findMatchingRuleFunc = f
+	return m
+}
+
+func (m *MockAssignmentRuleRepository) WithListCandidateLoadsFunc(f func(ctx context.Context, orgID uuid.UUID, targetModel string, userIDs []uuid.UUID) ([]types.UserAssignmentLoad, error)) *MockAssignmentRuleRepository {
+	m.listCandidateLoadsFunc = f
+	return m
+}
+
+func (m *MockAssignmentRuleRepository) WithGetUserNameFunc(f func(ctx context.Context, userID uuid.UUID) (string, error)) *MockAssignmentRuleRepository {
+	m.getUserNameFunc = f
+	return m
+}
+
+func (m *MockAssignmentRuleRepository) WithRecordAssignmentFunc(f func(ctx context.Context, record types.AssignmentRecord) error) *MockAssignmentRuleRepository {
+	m.recordAssignmentFunc = f
+	return m
+}
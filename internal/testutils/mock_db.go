@@ -2,22 +2,37 @@ package testutils
 
 import (
 	"database/sql"
+	"os"
 	"testing"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// SetupTestDB connects to the database named by TEST_DATABASE_URL and skips
+// the test when none is configured
 func SetupTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 
-	// For now, return nil as we need to set up a proper test database
-	// In a real implementation, this would connect to a test database
-	return nil
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	return db
 }
 
 func TeardownTestDB(t *testing.T, db *sql.DB) {
 	t.Helper()
 
-	// For now, do nothing as we don't have a real database connection
-	// In a real implementation, this would close the connection and clean up
+	if db != nil {
+		db.Close()
+	}
 }
@@ -17,12 +17,13 @@ type MockLeadRepository struct {
 	updateFunc              func(ctx context.Context, lead types.Lead) (*types.Lead, error)
 	deleteFunc              func(ctx context.Context, id uuid.UUID) error
 	countFunc               func(ctx context.Context, filter types.LeadFilter) (int, error)
-	countByStageFunc        func(ctx context.Context) (map[uuid.UUID]int, error)
-	findByDateRangeFunc     func(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error)
-	findByDeadlineRangeFunc func(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error)
-	findOverdueFunc         func(ctx context.Context) ([]types.Lead, error)
-	findHighValueFunc       func(ctx context.Context, minValue float64) ([]types.Lead, error)
-	findBySearchTermFunc    func(ctx context.Context, searchTerm string) ([]types.Lead, error)
+	countByStageFunc        func(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]int, error)
+	findByDateRangeFunc     func(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error)
+	findByDeadlineRangeFunc func(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error)
+	findOverdueFunc         func(ctx context.Context, orgID uuid.UUID) ([]types.Lead, error)
+	findHighValueFunc       func(ctx context.Context, orgID uuid.UUID, minValue float64) ([]types.Lead, error)
+	findBySearchTermFunc    func(ctx context.Context, orgID uuid.UUID, searchTerm string) ([]types.Lead, error)
+	findOpenByEmailFunc     func(ctx context.Context, orgID uuid.UUID, email string, since time.Time) (*types.Lead, error)
 }
 
 // NewMockLeadRepository creates a new mock lead repository
@@ -85,10 +86,27 @@ func (m *MockLeadRepository) Count(ctx context.Context, filter types.LeadFilter)
 	return 2, nil
 }
 
+// FindPage implements the repository interface with FindAll and Count
+func (m *MockLeadRepository) FindPage(ctx context.Context, filter types.LeadFilter, withTotal bool) (*types.LeadPage, error) {
+	leads, err := m.FindAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &types.LeadPage{Data: leads, Limit: filter.Limit, Offset: filter.Offset}
+	if withTotal {
+		total, err := m.Count(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
 // CountByStage implements the repository interface
-func (m *MockLeadRepository) CountByStage(ctx context.Context) (map[uuid.UUID]int, error) {
+func (m *MockLeadRepository) CountByStage(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]int, error) {
 	if m.countByStageFunc != nil {
-		return m.countByStageFunc(ctx)
+		return m.countByStageFunc(ctx, orgID)
 	}
 	// Return default mock data
 	stageID1 := uuid.Must(uuid.NewV7())
@@ -100,9 +118,9 @@ func (m *MockLeadRepository) CountByStage(ctx context.Context) (map[uuid.UUID]in
 }
 
 // FindByDateRange implements the repository interface
-func (m *MockLeadRepository) FindByDateRange(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error) {
+func (m *MockLeadRepository) FindByDateRange(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error) {
 	if m.findByDateRangeFunc != nil {
-		return m.findByDateRangeFunc(ctx, startDate, endDate)
+		return m.findByDateRangeFunc(ctx, orgID, startDate, endDate)
 	}
 	return []types.Lead{
 		{ID: uuid.Must(uuid.NewV7()), Name: "Lead in date range"},
@@ -110,9 +128,9 @@ func (m *MockLeadRepository) FindByDateRange(ctx context.Context, startDate, end
 }
 
 // FindByDeadlineRange implements the repository interface
-func (m *MockLeadRepository) FindByDeadlineRange(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error) {
+func (m *MockLeadRepository) FindByDeadlineRange(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error) {
 	if m.findByDeadlineRangeFunc != nil {
-		return m.findByDeadlineRangeFunc(ctx, startDate, endDate)
+		return m.findByDeadlineRangeFunc(ctx, orgID, startDate, endDate)
 	}
 	return []types.Lead{
 		{ID: uuid.Must(uuid.NewV7()), Name: "Lead with deadline in range"},
@@ -120,9 +138,9 @@ func (m *MockLeadRepository) FindByDeadlineRange(ctx context.Context, startDate,
 }
 
 // FindOverdue implements the repository interface
-func (m *MockLeadRepository) FindOverdue(ctx context.Context) ([]types.Lead, error) {
+func (m *MockLeadRepository) FindOverdue(ctx context.Context, orgID uuid.UUID) ([]types.Lead, error) {
 	if m.findOverdueFunc != nil {
-		return m.findOverdueFunc(ctx)
+		return m.findOverdueFunc(ctx, orgID)
 	}
 	return []types.Lead{
 		{ID: uuid.Must(uuid.NewV7()), Name: "Overdue Lead"},
@@ -130,9 +148,9 @@ func (m *MockLeadRepository) FindOverdue(ctx context.Context) ([]types.Lead, err
 }
 
 // FindHighValue implements the repository interface
-func (m *MockLeadRepository) FindHighValue(ctx context.Context, minValue float64) ([]types.Lead, error) {
+func (m *MockLeadRepository) FindHighValue(ctx context.Context, orgID uuid.UUID, minValue float64) ([]types.Lead, error) {
 	if m.findHighValueFunc != nil {
-		return m.findHighValueFunc(ctx, minValue)
+		return m.findHighValueFunc(ctx, orgID, minValue)
 	}
 	expectedRevenue := minValue + 1000
 	return []types.Lead{
@@ -141,9 +159,9 @@ func (m *MockLeadRepository) FindHighValue(ctx context.Context, minValue float64
 }
 
 // FindBySearchTerm implements the repository interface
-func (m *MockLeadRepository) FindBySearchTerm(ctx context.Context, searchTerm string) ([]types.Lead, error) {
+func (m *MockLeadRepository) FindBySearchTerm(ctx context.Context, orgID uuid.UUID, searchTerm string) ([]types.Lead, error) {
 	if m.findBySearchTermFunc != nil {
-		return m.findBySearchTermFunc(ctx, searchTerm)
+		return m.findBySearchTermFunc(ctx, orgID, searchTerm)
 	}
 	return []types.Lead{
 		{ID: uuid.Must(uuid.NewV7()), Name: "Lead matching " + searchTerm},
@@ -181,32 +199,45 @@ func (m *MockLeadRepository) WithCountFunc(f func(ctx context.Context, filter ty
 	return m
 }
 
-func (m *MockLeadRepository) WithCountByStageFunc(f func(ctx context.Context) (map[uuid.UUID]int, error)) *MockLeadRepository {
+func (m *MockLeadRepository) WithCountByStageFunc(f func(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]int, error)) *MockLeadRepository {
 	m.countByStageFunc = f
 	return m
 }
 
-func (m *MockLeadRepository) WithFindByDateRangeFunc(f func(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error)) *MockLeadRepository {
+func (m *MockLeadRepository) WithFindByDateRangeFunc(f func(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error)) *MockLeadRepository {
 	m.findByDateRangeFunc = f
 	return m
 }
 
-func (m *MockLeadRepository) WithFindByDeadlineRangeFunc(f func(ctx context.Context, startDate, endDate time.Time) ([]types.Lead, error)) *MockLeadRepository {
+func (m *MockLeadRepository) WithFindByDeadlineRangeFunc(f func(ctx context.Context, orgID uuid.UUID, startDate, endDate time.Time) ([]types.Lead, error)) *MockLeadRepository {
 	m.findByDeadlineRangeFunc = f
 	return m
 }
 
-func (m *MockLeadRepository) WithFindOverdueFunc(f func(ctx context.Context) ([]types.Lead, error)) *MockLeadRepository {
+func (m *MockLeadRepository) WithFindOverdueFunc(f func(ctx context.Context, orgID uuid.UUID) ([]types.Lead, error)) *MockLeadRepository {
 	m.findOverdueFunc = f
 	return m
 }
 
-func (m *MockLeadRepository) WithFindHighValueFunc(f func(ctx context.Context, minValue float64) ([]types.Lead, error)) *MockLeadRepository {
+func (m *MockLeadRepository) WithFindHighValueFunc(f func(ctx context.Context, orgID uuid.UUID, minValue float64) ([]types.Lead, error)) *MockLeadRepository {
 	m.findHighValueFunc = f
 	return m
 }
 
-func (m *MockLeadRepository) WithFindBySearchTermFunc(f func(ctx context.Context, searchTerm string) ([]types.Lead, error)) *MockLeadRepository {
+// FindOpenByEmailSince implements the repository interface
+func (m *MockLeadRepository) FindOpenByEmailSince(ctx context.Context, orgID uuid.UUID, email string, since time.Time) (*types.Lead, error) {
+	if m.findOpenByEmailFunc != nil {
+		return m.findOpenByEmailFunc(ctx, orgID, email, since)
+	}
+	return nil, nil
+}
+
+func (m *MockLeadRepository) WithFindOpenByEmailSinceFunc(f func(ctx context.Context, orgID uuid.UUID, email string, since time.Time) (*types.Lead, error)) *MockLeadRepository {
+	m.findOpenByEmailFunc = f
+	return m
+}
+
+func (m *MockLeadRepository) WithFindBySearchTermFunc(f func(ctx context.Context, orgID uuid.UUID, searchTerm string) ([]types.Lead, error)) *MockLeadRepository {
 	m.findBySearchTermFunc = f
 	return m
 }
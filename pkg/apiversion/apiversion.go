@@ -0,0 +1,91 @@
+// Package apiversion lets modules serve breaking changes of an endpoint
+// under a new API version while the previous version keeps answering.
+// Endpoints live under /api/v<N>/; a v1 endpoint with a v2 successor is
+// marked deprecated in its responses, and every versioned endpoint is listed
+// in a catalog with its deprecation and sunset dates and how much it is
+// still called. Clients may also keep their v1 paths and ask for another
+// version with the API-Version header.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Header is the request header asking for an API version, and the response
+// header telling which version answered
+const Header = "API-Version"
+
+// Version is an API version
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+// Prefix returns the path prefix of the version's endpoints
+func (v Version) Prefix() string {
+	return "/api/v" + strconv.Itoa(int(v))
+}
+
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// ParseVersion parses a version given as "2" or "v2"
+func ParseVersion(s string) (Version, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid API version %q", s)
+	}
+	return Version(n), nil
+}
+
+// SplitPath splits a versioned path such as /api/v2/contacts/:id into its
+// version and the path below the version prefix
+func SplitPath(path string) (Version, string, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return 0, "", false
+	}
+	digits, below, _ := strings.Cut(rest, "/")
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 || strconv.Itoa(n) != digits {
+		return 0, "", false
+	}
+	return Version(n), "/" + below, true
+}
+
+// Negotiate serves requests for a versioned path with the version asked for
+// in the API-Version header, when the router has that version of the
+// endpoint. Requests for versions the endpoint does not have are served by
+// the version in their path, which the API-Version response header reports.
+func Negotiate(router *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked := r.Header.Get(Header)
+		if asked == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		version, err := ParseVersion(asked)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if current, below, ok := SplitPath(r.URL.Path); ok && current != version {
+			path := version.Prefix() + below
+			if handle, _, _ := router.Lookup(r.Method, path); handle != nil {
+				r = r.Clone(r.Context())
+				r.URL.Path = path
+				r.URL.RawPath = ""
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
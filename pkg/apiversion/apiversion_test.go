@@ -0,0 +1,136 @@
+package apiversion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func answer(body string) httprouter.Handle {
+	return func(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+		w.Write([]byte(body + ps.ByName("id")))
+	}
+}
+
+func newVersionedRouter(catalog *Catalog) *httprouter.Router {
+	router := httprouter.New()
+	versions := NewRouter(router, catalog)
+	versions.GET("/api/v1/leads/:id", answer("v1 "))
+	versions.GET("/api/v2/leads/:id", answer("v2 "))
+	versions.POST("/api/v1/leads", answer("created"), DeprecatedAt(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	versions.GET("/api/v1/reports", answer("report"), SunsetAt(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)))
+	return router
+}
+
+func serve(handler http.Handler, method, path, version string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if version != "" {
+		req.Header.Set(Header, version)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSplitPath(t *testing.T) {
+	version, below, ok := SplitPath("/api/v2/contacts/:id")
+	assert.True(t, ok)
+	assert.Equal(t, V2, version)
+	assert.Equal(t, "/contacts/:id", below)
+
+	for _, path := range []string{"/api/crm/tags", "/api/v/x", "/api/v02/x", "/api/v0/x", "/health"} {
+		_, _, ok := SplitPath(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestSideBySideVersions(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.now = func() time.Time { return time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC) }
+	router := newVersionedRouter(catalog)
+
+	v1 := serve(router, http.MethodGet, "/api/v1/leads/42", "")
+	assert.Equal(t, "v1 42", v1.Body.String())
+	assert.Equal(t, "1", v1.Header().Get(Header))
+	assert.Equal(t, "true", v1.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v2/leads/42>; rel="successor-version"`, v1.Header().Get("Link"))
+
+	v2 := serve(router, http.MethodGet, "/api/v2/leads/42", "")
+	assert.Equal(t, "v2 42", v2.Body.String())
+	assert.Equal(t, "2", v2.Header().Get(Header))
+	assert.Empty(t, v2.Header().Get("Deprecation"))
+
+	dated := serve(router, http.MethodPost, "/api/v1/leads", "")
+	assert.Equal(t, "@1735689600", dated.Header().Get("Deprecation"))
+	assert.Empty(t, dated.Header().Get("Link"))
+}
+
+func TestSunset(t *testing.T) {
+	catalog := NewCatalog()
+	now := time.Date(2025, 6, 29, 23, 0, 0, 0, time.UTC)
+	catalog.now = func() time.Time { return now }
+	router := newVersionedRouter(catalog)
+
+	before := serve(router, http.MethodGet, "/api/v1/reports", "")
+	assert.Equal(t, http.StatusOK, before.Code)
+	assert.Equal(t, "Mon, 30 Jun 2025 00:00:00 GMT", before.Header().Get("Sunset"))
+
+	now = now.Add(time.Hour)
+	after := serve(router, http.MethodGet, "/api/v1/reports", "")
+	assert.Equal(t, http.StatusGone, after.Code)
+}
+
+func TestNegotiate(t *testing.T) {
+	router := newVersionedRouter(nil)
+	handler := Negotiate(router, router)
+
+	assert.Equal(t, "v2 7", serve(handler, http.MethodGet, "/api/v1/leads/7", "2").Body.String())
+	assert.Equal(t, "v1 7", serve(handler, http.MethodGet, "/api/v2/leads/7", "v1").Body.String())
+
+	// Endpoints without the version asked for answer with theirs
+	created := serve(handler, http.MethodPost, "/api/v1/leads", "2")
+	assert.Equal(t, "created", created.Body.String())
+	assert.Equal(t, "1", created.Header().Get(Header))
+
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodGet, "/api/v1/leads/7", "latest").Code)
+}
+
+func TestCatalogTracksCalls(t *testing.T) {
+	catalog := NewCatalog()
+	called := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	catalog.now = func() time.Time { return called }
+	router := newVersionedRouter(catalog)
+	serve(router, http.MethodGet, "/api/v1/leads/1", "")
+	serve(router, http.MethodGet, "/api/v1/leads/2", "")
+
+	rec := serve(catalog, http.MethodGet, "/api/v1/metadata/api-versions?deprecated=true", "")
+	var body struct {
+		Endpoints []Endpoint `json:"endpoints"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.Len(t, body.Endpoints, 3)
+
+	byPath := map[string]Endpoint{}
+	for _, e := range body.Endpoints {
+		byPath[e.Method+" "+e.Path] = e
+	}
+	leads := byPath["GET /api/v1/leads/:id"]
+	assert.Equal(t, "/api/v2/leads/:id", leads.Successor)
+	assert.Equal(t, int64(2), leads.Calls)
+	require.NotNil(t, leads.LastCalledAt)
+	assert.True(t, called.Equal(*leads.LastCalledAt))
+	assert.Contains(t, byPath, "POST /api/v1/leads")
+	assert.Contains(t, byPath, "GET /api/v1/reports")
+}
+
+func TestHandleRequiresVersionedPath(t *testing.T) {
+	assert.Panics(t, func() {
+		NewRouter(httprouter.New(), nil).GET("/api/crm/tags", answer(""))
+	})
+}
@@ -0,0 +1,232 @@
+package apiversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Endpoint is a versioned endpoint as listed in the catalog
+type Endpoint struct {
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Version Version `json:"version"`
+	// Successor is the path of the next version of the endpoint, if any
+	Successor    string     `json:"successor,omitempty"`
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	// Sunset is when the endpoint stops answering; from then on it answers
+	// 410 Gone
+	Sunset       *time.Time `json:"sunset,omitempty"`
+	Calls        int64      `json:"calls"`
+	LastCalledAt *time.Time `json:"last_called_at,omitempty"`
+}
+
+// Deprecated reports whether the endpoint has a successor, a deprecation
+// date or a sunset
+func (e Endpoint) Deprecated() bool {
+	return e.Successor != "" || e.DeprecatedAt != nil || e.Sunset != nil
+}
+
+// Option sets the lifecycle of an endpoint when it is registered
+type Option func(*Endpoint)
+
+// DeprecatedAt dates the deprecation of an endpoint. Endpoints with a
+// successor are deprecated without one.
+func DeprecatedAt(at time.Time) Option {
+	return func(e *Endpoint) {
+		at = at.UTC()
+		e.DeprecatedAt = &at
+	}
+}
+
+// SunsetAt sets when an endpoint stops answering
+func SunsetAt(at time.Time) Option {
+	return func(e *Endpoint) {
+		at = at.UTC()
+		e.Sunset = &at
+	}
+}
+
+// Catalog tracks the versioned endpoints of all modules. The zero value is
+// not usable; a nil catalog serves endpoints without tracking them.
+type Catalog struct {
+	mu        sync.Mutex
+	endpoints map[string]*Endpoint // by method and versioned path
+	now       func() time.Time
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{endpoints: make(map[string]*Endpoint), now: time.Now}
+}
+
+func endpointKey(method string, version Version, below string) string {
+	return method + " " + version.Prefix() + below
+}
+
+// add records an endpoint and links it with its neighbouring versions
+func (c *Catalog) add(e *Endpoint) *Endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, below, _ := SplitPath(e.Path)
+	if existing, ok := c.endpoints[endpointKey(e.Method, e.Version, below)]; ok {
+		return existing
+	}
+	c.endpoints[endpointKey(e.Method, e.Version, below)] = e
+
+	if next, ok := c.endpoints[endpointKey(e.Method, e.Version+1, below)]; ok {
+		e.Successor = next.Path
+	}
+	if previous, ok := c.endpoints[endpointKey(e.Method, e.Version-1, below)]; ok {
+		previous.Successor = e.Path
+	}
+	return e
+}
+
+// called counts a call of an endpoint and returns its lifecycle
+func (c *Catalog) called(e *Endpoint) (Endpoint, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now().UTC()
+	e.Calls++
+	e.LastCalledAt = &now
+	return *e, now
+}
+
+// Endpoints lists the endpoints by path, method and version
+func (c *Catalog) Endpoints() []Endpoint {
+	if c == nil {
+		return []Endpoint{}
+	}
+	c.mu.Lock()
+	list := make([]Endpoint, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		list = append(list, *e)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		_, a, _ := SplitPath(list[i].Path)
+		_, b, _ := SplitPath(list[j].Path)
+		if a != b {
+			return a < b
+		}
+		if list[i].Method != list[j].Method {
+			return list[i].Method < list[j].Method
+		}
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// ServeHTTP lists the endpoints, with deprecated=true only the deprecated
+// ones
+func (c *Catalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoints := c.Endpoints()
+	if r.URL.Query().Get("deprecated") == "true" {
+		deprecated := []Endpoint{}
+		for _, e := range endpoints {
+			if e.Deprecated() {
+				deprecated = append(deprecated, e)
+			}
+		}
+		endpoints = deprecated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"endpoints": endpoints})
+}
+
+// Router registers versioned endpoints on a router and in a catalog
+type Router struct {
+	router  *httprouter.Router
+	catalog *Catalog
+}
+
+// NewRouter creates a router registering on router and in catalog, which
+// may be nil
+func NewRouter(router *httprouter.Router, catalog *Catalog) *Router {
+	return &Router{router: router, catalog: catalog}
+}
+
+// Handle registers a handle for a versioned path such as /api/v2/contacts.
+// Its responses carry the API-Version header and, once the endpoint is
+// deprecated, the Deprecation, Sunset and successor Link headers. It panics
+// on paths without a version, as the router does on invalid paths.
+func (vr *Router) Handle(method, path string, handle httprouter.Handle, opts ...Option) {
+	version, _, ok := SplitPath(path)
+	if !ok {
+		panic(fmt.Sprintf("apiversion: path %q has no /api/v<N>/ prefix", path))
+	}
+	e := &Endpoint{Method: method, Path: path, Version: version}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if vr.catalog != nil {
+		e = vr.catalog.add(e)
+	}
+	vr.router.Handle(method, path, vr.serve(e, handle))
+}
+
+// GET registers a handle for GET requests to a versioned path
+func (vr *Router) GET(path string, handle httprouter.Handle, opts ...Option) {
+	vr.Handle(http.MethodGet, path, handle, opts...)
+}
+
+// POST registers a handle for POST requests to a versioned path
+func (vr *Router) POST(path string, handle httprouter.Handle, opts ...Option) {
+	vr.Handle(http.MethodPost, path, handle, opts...)
+}
+
+// PUT registers a handle for PUT requests to a versioned path
+func (vr *Router) PUT(path string, handle httprouter.Handle, opts ...Option) {
+	vr.Handle(http.MethodPut, path, handle, opts...)
+}
+
+// PATCH registers a handle for PATCH requests to a versioned path
+func (vr *Router) PATCH(path string, handle httprouter.Handle, opts ...Option) {
+	vr.Handle(http.MethodPatch, path, handle, opts...)
+}
+
+// DELETE registers a handle for DELETE requests to a versioned path
+func (vr *Router) DELETE(path string, handle httprouter.Handle, opts ...Option) {
+	vr.Handle(http.MethodDelete, path, handle, opts...)
+}
+
+func (vr *Router) serve(e *Endpoint, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		lifecycle, now := *e, time.Now().UTC()
+		if vr.catalog != nil {
+			lifecycle, now = vr.catalog.called(e)
+		}
+
+		w.Header().Set(Header, strconv.Itoa(int(lifecycle.Version)))
+		if lifecycle.Deprecated() {
+			if lifecycle.DeprecatedAt != nil {
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(lifecycle.DeprecatedAt.Unix(), 10))
+			} else {
+				w.Header().Set("Deprecation", "true")
+			}
+			if lifecycle.Successor != "" {
+				if next, _, ok := SplitPath(lifecycle.Successor); ok {
+					_, below, _ := SplitPath(r.URL.Path)
+					w.Header().Set("Link", "<"+next.Prefix()+below+`>; rel="successor-version"`)
+				}
+			}
+		}
+		if lifecycle.Sunset != nil {
+			w.Header().Set("Sunset", lifecycle.Sunset.Format(http.TimeFormat))
+			if !now.Before(*lifecycle.Sunset) {
+				http.Error(w, fmt.Sprintf("%s %s was retired on %s", e.Method, e.Path, lifecycle.Sunset.Format(time.RFC3339)), http.StatusGone)
+				return
+			}
+		}
+		handle(w, r, ps)
+	}
+}
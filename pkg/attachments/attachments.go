@@ -0,0 +1,319 @@
+// Package attachments keeps files attached to records, such as leads,
+// invoices or proofs of delivery, in the configured file storage, with their
+// metadata in the attachments table of the record's organization
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when an attachment is not one of the record's
+	ErrNotFound = errors.New("attachment not found")
+	// ErrTooLarge is returned for files over the policy's size limit
+	ErrTooLarge = errors.New("attachment too large")
+	// ErrTypeNotAllowed is returned for files of a type the policy does not
+	// allow
+	ErrTypeNotAllowed = errors.New("attachment type not allowed")
+	// ErrInvalid is returned for uploads missing a file name or content
+	ErrInvalid = errors.New("invalid attachment")
+	// ErrStorageUnavailable is returned when no file storage is configured
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+)
+
+// DefaultURLExpiry is how long download URLs stay valid
+const DefaultURLExpiry = 15 * time.Minute
+
+// Attachment is a file attached to a record
+type Attachment struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	FileName       string     `json:"file_name"`
+	FileSize       int64      `json:"file_size"`
+	MimeType       string     `json:"mimetype"`
+	Checksum       string     `json:"checksum"`
+	StorageKey     string     `json:"-"`
+	ResModel       string     `json:"res_model"`
+	ResID          uuid.UUID  `json:"res_id"`
+	ResName        string     `json:"res_name,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	// DownloadURL is a signed URL to the file, valid until URLExpiresAt
+	DownloadURL  string     `json:"download_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// Upload is a file to attach to a record
+type Upload struct {
+	OrganizationID uuid.UUID
+	ResModel       string
+	ResID          uuid.UUID
+	ResName        string
+	FileName       string
+	// ContentType is the type the client declared, checked against the
+	// content
+	ContentType string
+	// Size is the declared size, -1 when unknown. The bytes read are
+	// limited whatever it says.
+	Size      int64
+	Reader    io.Reader
+	CreatedBy *uuid.UUID
+}
+
+// Policy bounds what may be uploaded
+type Policy struct {
+	MaxSize int64
+	// AllowedTypes are the MIME types files may have; "image/*" allows a
+	// family
+	AllowedTypes []string
+}
+
+// DefaultPolicy allows office documents, PDFs, images, text and archives up
+// to 25 MB
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxSize: 25 << 20,
+		AllowedTypes: []string{
+			"application/pdf",
+			"image/*",
+			"text/plain",
+			"text/csv",
+			"application/zip",
+			"application/msword",
+			"application/vnd.ms-excel",
+			"application/vnd.ms-powerpoint",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			"application/vnd.oasis.opendocument.text",
+			"application/vnd.oasis.opendocument.spreadsheet",
+			"message/rfc822",
+		},
+	}
+}
+
+// Allows reports whether the policy allows the MIME type
+func (p Policy) Allows(mimeType string) bool {
+	family, _, _ := strings.Cut(mimeType, "/")
+	for _, allowed := range p.AllowedTypes {
+		if allowed == mimeType || allowed == family+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// FileStore is the file storage attachment files are kept in
+type FileStore interface {
+	Upload(ctx context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error)
+	Delete(ctx context.Context, key string) error
+	GetURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Service stores attachments and hands out download URLs
+type Service struct {
+	store     *Store
+	files     FileStore
+	provider  string
+	policy    Policy
+	urlExpiry time.Duration
+	now       func() time.Time
+}
+
+// NewService creates an attachment service. Uploads fail with
+// ErrStorageUnavailable until SetStorage is called.
+func NewService(store *Store, policy Policy) *Service {
+	return &Service{
+		store:     store,
+		policy:    policy,
+		urlExpiry: DefaultURLExpiry,
+		now:       time.Now,
+	}
+}
+
+// SetStorage sets the file storage and the provider name recorded with
+// each attachment
+func (s *Service) SetStorage(files FileStore, provider string) {
+	if provider == "" {
+		provider = "local"
+	}
+	s.files = files
+	s.provider = provider
+}
+
+// Policy returns the upload policy, for handlers to bound request bodies
+func (s *Service) Policy() Policy {
+	return s.policy
+}
+
+// unsafeFileNameChars are replaced in storage keys; the original file name
+// is kept in the metadata
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sniffLen is how much content http.DetectContentType looks at
+const sniffLen = 512
+
+// Upload checks the file against the policy, stores it and records it. The
+// declared type must be allowed, content that sniffs as HTML is refused
+// whatever it is declared as since browsers would render it, and at most
+// MaxSize bytes are read.
+func (s *Service) Upload(ctx context.Context, upload Upload) (*Attachment, error) {
+	if s.files == nil {
+		return nil, ErrStorageUnavailable
+	}
+	fileName := strings.TrimSpace(path.Base(strings.ReplaceAll(upload.FileName, `\`, "/")))
+	if fileName == "" || fileName == "." || fileName == "/" || upload.Reader == nil {
+		return nil, fmt.Errorf("%w: a file with a name is required", ErrInvalid)
+	}
+	if upload.Size > s.policy.MaxSize {
+		return nil, fmt.Errorf("%w: %d bytes is over the %d byte limit", ErrTooLarge, upload.Size, s.policy.MaxSize)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(upload.Reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	head = head[:n]
+	if n == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalid)
+	}
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	mimeType, _, err := mime.ParseMediaType(upload.ContentType)
+	if err != nil || mimeType == "application/octet-stream" {
+		mimeType = sniffed
+	}
+	if sniffed == "text/html" || !s.policy.Allows(mimeType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, mimeType)
+	}
+
+	attachment := Attachment{
+		ID:             uuid.New(),
+		OrganizationID: upload.OrganizationID,
+		Name:           fileName,
+		FileName:       fileName,
+		MimeType:       mimeType,
+		ResModel:       upload.ResModel,
+		ResID:          upload.ResID,
+		ResName:        upload.ResName,
+		CreatedBy:      upload.CreatedBy,
+	}
+	attachment.StorageKey = path.Join("attachments", upload.OrganizationID.String(), upload.ResModel,
+		upload.ResID.String(), attachment.ID.String(), unsafeFileNameChars.ReplaceAllString(fileName, "_"))
+
+	hash := sha256.New()
+	counted := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head), upload.Reader), s.policy.MaxSize+1)}
+	if _, err := s.files.Upload(ctx, storage.UploadOptions{
+		Key:         attachment.StorageKey,
+		Reader:      io.TeeReader(counted, hash),
+		ContentType: mimeType,
+		Size:        upload.Size,
+		Metadata: map[string]string{
+			"organization_id": upload.OrganizationID.String(),
+			"res_model":       upload.ResModel,
+			"res_id":          upload.ResID.String(),
+		},
+		ACL: "private",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if counted.n > s.policy.MaxSize {
+		_ = s.files.Delete(ctx, attachment.StorageKey)
+		return nil, fmt.Errorf("%w: the file is over the %d byte limit", ErrTooLarge, s.policy.MaxSize)
+	}
+	attachment.FileSize = counted.n
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.store.Create(ctx, &attachment, s.provider); err != nil {
+		_ = s.files.Delete(ctx, attachment.StorageKey)
+		return nil, err
+	}
+	return &attachment, s.sign(ctx, &attachment)
+}
+
+// List returns the record's attachments, newest first, with download URLs
+func (s *Service) List(ctx context.Context, orgID uuid.UUID, resModel string, resID uuid.UUID) ([]Attachment, error) {
+	attachments, err := s.store.List(ctx, orgID, resModel, resID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if err := s.sign(ctx, &attachments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// Get returns one of the record's attachments with a fresh download URL
+func (s *Service) Get(ctx context.Context, orgID uuid.UUID, resModel string, resID, id uuid.UUID) (*Attachment, error) {
+	attachment, err := s.store.Get(ctx, orgID, resModel, resID, id)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, s.sign(ctx, attachment)
+}
+
+// Delete removes one of the record's attachments and its file
+func (s *Service) Delete(ctx context.Context, orgID uuid.UUID, resModel string, resID, id uuid.UUID) error {
+	if s.files == nil {
+		return ErrStorageUnavailable
+	}
+	attachment, err := s.store.Get(ctx, orgID, resModel, resID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, orgID, id); err != nil {
+		return err
+	}
+	if err := s.files.Delete(ctx, attachment.StorageKey); err != nil {
+		return fmt.Errorf("attachment removed but its file could not be deleted: %w", err)
+	}
+	return nil
+}
+
+// sign sets the download URL of an attachment; without storage there is
+// none
+func (s *Service) sign(ctx context.Context, attachment *Attachment) error {
+	if s.files == nil {
+		return nil
+	}
+	url, err := s.files.GetURL(ctx, attachment.StorageKey, s.urlExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to sign attachment URL: %w", err)
+	}
+	expiresAt := s.now().Add(s.urlExpiry)
+	attachment.DownloadURL = url
+	attachment.URLExpiresAt = &expiresAt
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
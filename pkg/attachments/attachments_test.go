@@ -0,0 +1,111 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KevTiv/alieze-erp/pkg/storage"
+)
+
+type memoryFiles struct {
+	files map[string][]byte
+}
+
+func (m *memoryFiles) Upload(_ context.Context, opts storage.UploadOptions) (*storage.FileMetadata, error) {
+	data, err := io.ReadAll(opts.Reader)
+	if err != nil {
+		return nil, err
+	}
+	m.files[opts.Key] = data
+	return &storage.FileMetadata{Key: opts.Key, Size: int64(len(data))}, nil
+}
+
+func (m *memoryFiles) Delete(_ context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+func (m *memoryFiles) GetURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://files.example.com/" + key + "?signature=x", nil
+}
+
+func newTestService(t *testing.T, policy Policy) (*Service, sqlmock.Sqlmock, *memoryFiles) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	files := &memoryFiles{files: map[string][]byte{}}
+	service := NewService(NewStore(db), policy)
+	service.SetStorage(files, "s3")
+	return service, mock, files
+}
+
+func TestUploadStoresAndRecordsTheFile(t *testing.T) {
+	service, mock, files := newTestService(t, DefaultPolicy())
+	orgID, leadID := uuid.New(), uuid.New()
+	content := []byte("%PDF-1.4 quote")
+
+	mock.ExpectQuery(`INSERT INTO attachments`).
+		WithArgs(sqlmock.AnyArg(), orgID, "quote (v2).pdf", "quote (v2).pdf", int64(len(content)), "application/pdf",
+			"2c9636c852a244f77b3e0142eef00678e25a4759605a5c2e6576285fc7adf662", "s3", sqlmock.AnyArg(),
+			"leads", leadID, "Acme", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+	attachment, err := service.Upload(context.Background(), Upload{
+		OrganizationID: orgID, ResModel: "leads", ResID: leadID, ResName: "Acme",
+		FileName: `C:\Users\me\quote (v2).pdf`, ContentType: "application/octet-stream",
+		Size: -1, Reader: bytes.NewReader(content),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", attachment.MimeType)
+	assert.True(t, strings.HasSuffix(attachment.StorageKey, "/quote_v2_.pdf"), attachment.StorageKey)
+	assert.Equal(t, content, files.files[attachment.StorageKey])
+	assert.Contains(t, attachment.DownloadURL, attachment.StorageKey)
+	assert.NotNil(t, attachment.URLExpiresAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUploadEnforcesThePolicy(t *testing.T) {
+	service, mock, files := newTestService(t, Policy{MaxSize: 16, AllowedTypes: []string{"application/pdf", "image/*"}})
+	upload := func(name, contentType string, size int64, content string) error {
+		_, err := service.Upload(context.Background(), Upload{
+			OrganizationID: uuid.New(), ResModel: "leads", ResID: uuid.New(),
+			FileName: name, ContentType: contentType, Size: size, Reader: strings.NewReader(content),
+		})
+		return err
+	}
+
+	assert.ErrorIs(t, upload("a.pdf", "application/pdf", 17, "%PDF"), ErrTooLarge)
+	// The declared size is not trusted
+	assert.ErrorIs(t, upload("a.pdf", "application/pdf", 4, "%PDF-1.4 and much more"), ErrTooLarge)
+	assert.ErrorIs(t, upload("a.exe", "application/x-msdownload", -1, "MZ"), ErrTypeNotAllowed)
+	assert.ErrorIs(t, upload("a.pdf", "application/pdf", -1, "<html><script>"), ErrTypeNotAllowed)
+	assert.ErrorIs(t, upload("", "application/pdf", -1, "%PDF"), ErrInvalid)
+	assert.ErrorIs(t, upload("a.pdf", "application/pdf", -1, ""), ErrInvalid)
+
+	assert.Empty(t, files.files)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPolicyAllows(t *testing.T) {
+	policy := Policy{AllowedTypes: []string{"application/pdf", "image/*"}}
+	assert.True(t, policy.Allows("image/png"))
+	assert.True(t, policy.Allows("application/pdf"))
+	assert.False(t, policy.Allows("application/pdfx"))
+	assert.False(t, policy.Allows("text/html"))
+}
+
+func TestUploadWithoutStorage(t *testing.T) {
+	service := NewService(NewStore(nil), DefaultPolicy())
+	_, err := service.Upload(context.Background(), Upload{FileName: "a.pdf", Reader: strings.NewReader("%PDF")})
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+}
@@ -0,0 +1,114 @@
+package attachments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Store keeps attachment metadata in the attachments table
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates an attachment store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const attachmentColumns = `id, organization_id, name, file_name, file_size, mimetype, COALESCE(checksum, ''),
+	storage_key, res_model, res_id, COALESCE(res_name, ''), created_at, created_by`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	var a Attachment
+	err := row.Scan(&a.ID, &a.OrganizationID, &a.Name, &a.FileName, &a.FileSize, &a.MimeType, &a.Checksum,
+		&a.StorageKey, &a.ResModel, &a.ResID, &a.ResName, &a.CreatedAt, &a.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Create records an attachment stored with the provider and sets its
+// creation time
+func (s *Store) Create(ctx context.Context, a *Attachment, provider string) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO attachments (
+			id, organization_id, name, file_name, file_size, mimetype, checksum,
+			storage_provider, storage_key, res_model, res_id, res_name, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $13)
+		RETURNING created_at`,
+		a.ID, a.OrganizationID, a.Name, a.FileName, a.FileSize, a.MimeType, a.Checksum,
+		provider, a.StorageKey, a.ResModel, a.ResID, a.ResName, a.CreatedBy,
+	).Scan(&a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record attachment: %w", err)
+	}
+	return nil
+}
+
+// List returns the attachments of a record, newest first
+func (s *Store) List(ctx context.Context, orgID uuid.UUID, resModel string, resID uuid.UUID) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE organization_id = $1 AND res_model = $2 AND res_id = $3
+		ORDER BY created_at DESC, id`,
+		orgID, resModel, resID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// Get returns an attachment of a record, or ErrNotFound
+func (s *Store) Get(ctx context.Context, orgID uuid.UUID, resModel string, resID, id uuid.UUID) (*Attachment, error) {
+	a, err := scanAttachment(s.db.QueryRowContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE id = $1 AND organization_id = $2 AND res_model = $3 AND res_id = $4`,
+		id, orgID, resModel, resID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return a, nil
+}
+
+// Delete removes the metadata of an attachment
+func (s *Store) Delete(ctx context.Context, orgID, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
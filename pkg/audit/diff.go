@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldChange is a field's value before and after an update
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Diff compares two values of the same struct type field by field and
+// returns the changed fields in declaration order. Fields are named by their
+// json tag; fields tagged json:"-", unexported fields and the names in skip
+// are ignored. Pointers are compared by the value they point to and reported
+// dereferenced, with nil for a nil pointer.
+func Diff(before, after interface{}, skip ...string) []FieldChange {
+	b, a := reflect.ValueOf(before), reflect.ValueOf(after)
+	if b.Kind() == reflect.Ptr {
+		b = b.Elem()
+	}
+	if a.Kind() == reflect.Ptr {
+		a = a.Elem()
+	}
+	if b.Kind() != reflect.Struct || b.Type() != a.Type() {
+		return nil
+	}
+
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	changes := []FieldChange{}
+	for i := 0; i < b.NumField(); i++ {
+		field := b.Type().Field(i)
+		name := fieldName(field)
+		if name == "" || skipped[name] {
+			continue
+		}
+		old, new := value(b.Field(i)), value(a.Field(i))
+		if !equal(old, new) {
+			changes = append(changes, FieldChange{Field: name, Old: old, New: new})
+		}
+	}
+	return changes
+}
+
+// ChangeMap returns the changes keyed by field, each with its old and new
+// value, as carried by update events
+func ChangeMap(changes []FieldChange) map[string]interface{} {
+	m := make(map[string]interface{}, len(changes))
+	for _, c := range changes {
+		m[c.Field] = map[string]interface{}{"old": c.Old, "new": c.New}
+	}
+	return m
+}
+
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// value dereferences pointers so that two pointers to equal values compare
+// equal and nil reads as no value
+func value(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+func equal(a, b interface{}) bool {
+	// Times are equal at the same instant whatever their location
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	// A nil slice or map holds the same nothing as an empty one
+	if empty(a) && empty(b) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	Name    string    `json:"name"`
+	Email   *string   `json:"email,omitempty"`
+	Tags    []string  `json:"tags"`
+	Seen    time.Time `json:"seen"`
+	Secret  string    `json:"-"`
+	Updated time.Time `json:"updated_at"`
+	hidden  int
+}
+
+func TestDiff(t *testing.T) {
+	seen := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldEmail, sameEmail := "a@example.com", "a@example.com"
+	before := record{Name: "Ada", Email: &oldEmail, Seen: seen, Secret: "x", Updated: seen, hidden: 1}
+	after := record{Name: "Ada", Email: &sameEmail, Tags: []string{}, Seen: seen.In(time.FixedZone("CET", 3600)),
+		Secret: "y", Updated: seen.Add(time.Hour), hidden: 2}
+
+	// Equal pointees, empty slices and the same instant elsewhere are no change
+	assert.Empty(t, Diff(before, &after, "updated_at"))
+
+	after.Name, after.Email, after.Tags = "Grace", nil, []string{"vip"}
+	changes := Diff(&before, after, "updated_at")
+	assert.Equal(t, []FieldChange{
+		{Field: "name", Old: "Ada", New: "Grace"},
+		{Field: "email", Old: "a@example.com", New: nil},
+		{Field: "tags", Old: []string(nil), New: []string{"vip"}},
+	}, changes)
+	assert.Equal(t, map[string]interface{}{"old": "Ada", "new": "Grace"}, ChangeMap(changes)["name"])
+}
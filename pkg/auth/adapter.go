@@ -51,7 +51,11 @@ func NewPolicyAuthAdapterWithRules(policyEngine, ruleEngine interface{}) *Policy
 // LegacyAuthService implementation
 
 func (a *PolicyAuthAdapter) GetOrganizationID(ctx context.Context) (uuid.UUID, error) {
-	// Extract organization ID from context (set by auth middleware)
+	if authCtx, err := FromContext(ctx); err == nil {
+		return authCtx.OrganizationID, nil
+	}
+
+	// Fall back to the legacy context key
 	orgID, ok := ctx.Value("organizationID").(uuid.UUID)
 	if !ok {
 		a.logger.Error("Organization ID not found in context")
@@ -61,7 +65,11 @@ func (a *PolicyAuthAdapter) GetOrganizationID(ctx context.Context) (uuid.UUID, e
 }
 
 func (a *PolicyAuthAdapter) GetUserID(ctx context.Context) (uuid.UUID, error) {
-	// Extract user ID from context (set by auth middleware)
+	if authCtx, err := FromContext(ctx); err == nil {
+		return authCtx.UserID, nil
+	}
+
+	// Fall back to the legacy context key
 	userID, ok := ctx.Value("userID").(uuid.UUID)
 	if !ok {
 		a.logger.Error("User ID not found in context")
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnauthenticated is returned when a request carries no resolved auth context
+var ErrUnauthenticated = errors.New("unauthenticated: auth context not found")
+
+type authContextKey struct{}
+
+// AuthContext is the request-scoped identity resolved once by the auth middleware.
+// Handlers extract it a single time and pass it explicitly to services, so
+// services and repositories never need to re-read identity from the context.
+type AuthContext struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CompanyID      *uuid.UUID `json:"company_id,omitempty"`
+	Roles          []string   `json:"roles"`
+	IsSuperAdmin   bool       `json:"is_super_admin"`
+	IsSandbox      bool       `json:"is_sandbox"`
+}
+
+// HasRole reports whether the auth context carries the given role
+func (a *AuthContext) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryRole returns the first role, which is the role used for policy checks
+func (a *AuthContext) PrimaryRole() string {
+	if len(a.Roles) == 0 {
+		return ""
+	}
+	return a.Roles[0]
+}
+
+// WithAuthContext stores the auth context on ctx.
+// The legacy string keys are populated as well so code that has not yet been
+// migrated to AuthContext keeps working.
+func WithAuthContext(ctx context.Context, ac *AuthContext) context.Context {
+	ctx = context.WithValue(ctx, authContextKey{}, ac)
+	ctx = context.WithValue(ctx, "userID", ac.UserID)
+	ctx = context.WithValue(ctx, "organizationID", ac.OrganizationID)
+	ctx = context.WithValue(ctx, "orgID", ac.OrganizationID)
+	ctx = context.WithValue(ctx, "role", ac.PrimaryRole())
+	ctx = context.WithValue(ctx, "isSuperAdmin", ac.IsSuperAdmin)
+	return ctx
+}
+
+// FromContext returns the auth context stored on ctx
+func FromContext(ctx context.Context) (*AuthContext, error) {
+	ac, ok := ctx.Value(authContextKey{}).(*AuthContext)
+	if !ok || ac == nil || ac.OrganizationID == uuid.Nil {
+		return nil, ErrUnauthenticated
+	}
+	return ac, nil
+}
+
+// RequireAuthContext extracts the auth context for an HTTP handler, writing a
+// 401 response and returning false when the request is not authenticated.
+func RequireAuthContext(w http.ResponseWriter, r *http.Request) (*AuthContext, bool) {
+	ac, err := FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return ac, true
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthContextRoundTrip(t *testing.T) {
+	ac := &AuthContext{
+		UserID:         uuid.New(),
+		OrganizationID: uuid.New(),
+		Roles:          []string{"sales"},
+	}
+	ctx := WithAuthContext(context.Background(), ac)
+
+	got, err := FromContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ac, got)
+	assert.True(t, got.HasRole("sales"))
+	assert.Equal(t, "sales", got.PrimaryRole())
+
+	// Legacy keys stay populated for code still reading them directly
+	assert.Equal(t, ac.OrganizationID, ctx.Value("organizationID"))
+	assert.Equal(t, "sales", ctx.Value("role"))
+
+	adapter := NewPolicyAuthAdapter(nil)
+	orgID, err := adapter.GetOrganizationID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ac.OrganizationID, orgID)
+}
+
+func TestFromContextMissing(t *testing.T) {
+	_, err := FromContext(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	ctx := WithAuthContext(context.Background(), &AuthContext{UserID: uuid.New()})
+	_, err = FromContext(ctx)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestRequireAuthContext(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := RequireAuthContext(rec, req)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	ac := &AuthContext{UserID: uuid.New(), OrganizationID: uuid.New()}
+	rec = httptest.NewRecorder()
+	req = req.WithContext(WithAuthContext(req.Context(), ac))
+	got, ok := RequireAuthContext(rec, req)
+	assert.True(t, ok)
+	assert.Equal(t, ac.OrganizationID, got.OrganizationID)
+}
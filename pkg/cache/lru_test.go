@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUExpiresAndEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}
+	c := NewLRU(2)
+	c.now = clock.Now
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+
+	// Reading a makes b the least recently used
+	value, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+	assert.Equal(t, 2, c.Len())
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok)
+
+	clock.Advance(time.Minute)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.False(t, ok)
+	value, ok, _ = c.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", string(value))
+
+	require.NoError(t, c.Delete(ctx, "c", "missing"))
+	assert.Equal(t, 0, c.Len())
+}
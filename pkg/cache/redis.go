@@ -0,0 +1,316 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of a Redis store
+const (
+	DefaultRedisTimeout  = 2 * time.Second
+	DefaultRedisPoolSize = 10
+	// maxRedisBulk bounds the values read from the server
+	maxRedisBulk = 64 << 20
+)
+
+// RedisOptions configures a Redis store
+type RedisOptions struct {
+	// Addr is the server's host:port
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	TLS      bool
+	// Prefix is prepended to every key, so that deployments can share a
+	// server
+	Prefix string
+	// Timeout bounds connecting and each command, unless the context's
+	// deadline is earlier
+	Timeout time.Duration
+	// PoolSize bounds the idle connections kept for reuse
+	PoolSize int
+}
+
+// ParseRedisURL reads the options of a redis:// or, for TLS, rediss:// URL
+// such as redis://:password@localhost:6379/0
+func ParseRedisURL(rawURL string) (RedisOptions, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RedisOptions{}, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return RedisOptions{}, fmt.Errorf("invalid Redis URL: scheme must be redis or rediss, not %q", u.Scheme)
+	}
+
+	opts := RedisOptions{Addr: u.Host, TLS: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.Hostname() == "" {
+		return RedisOptions{}, errors.New("invalid Redis URL: host is required")
+	}
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		opts.DB, err = strconv.Atoi(db)
+		if err != nil || opts.DB < 0 {
+			return RedisOptions{}, fmt.Errorf("invalid Redis URL: database must be a number, not %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// RedisError is an error reply of the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// Redis is a Store kept in a Redis server and shared by every instance
+// using it. It speaks the RESP protocol over a small pool of connections.
+type Redis struct {
+	opts RedisOptions
+	idle chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis creates a store on the server of opts. Connections are made as
+// commands need them.
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultRedisTimeout
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = DefaultRedisPoolSize
+	}
+	return &Redis{opts: opts, idle: make(chan *redisConn, opts.PoolSize)}
+}
+
+// Get returns the value of key, and false when it is missing or expired
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", r.opts.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores the value of key for ttl; a zero ttl never expires
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", r.opts.Prefix + key, string(value)}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Delete removes keys; missing keys are ignored
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, r.opts.Prefix+key)
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Ping checks that the server can be reached
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections. Commands still running close theirs
+// when they finish.
+func (r *Redis) Close() error {
+	for {
+		select {
+		case c := <-r.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs a command on a pooled connection. A connection that failed other
+// than with an error reply is closed rather than reused, since its stream
+// may be out of step.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(ctx, r.opts.Timeout, args...)
+	var replyErr RedisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection or makes one, authenticated and on the
+// configured database
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-r.idle:
+		return c, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: r.opts.Timeout}
+	var conn net.Conn
+	var err error
+	if r.opts.TLS {
+		host, _, _ := net.SplitHostPort(r.opts.Addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", r.opts.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.opts.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to connect: %w", err)
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if r.opts.Password != "" {
+		args := []string{"AUTH", r.opts.Password}
+		if r.opts.Username != "" {
+			args = []string{"AUTH", r.opts.Username, r.opts.Password}
+		}
+		if _, err := c.do(ctx, r.opts.Timeout, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.opts.DB != 0 {
+		if _, err := c.do(ctx, r.opts.Timeout, "SELECT", strconv.Itoa(r.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: failed to send %s: %w", args[0], err)
+	}
+	return readReply(c.reader)
+}
+
+// readReply reads a RESP reply: a string, error, integer, bulk string (nil
+// when null) or array of replies
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to read reply: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RedisError(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", body)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 || n > maxRedisBulk {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis: failed to read reply: %w", err)
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			var replyErr RedisError
+			if errors.As(err, &replyErr) {
+				// Keep reading so the stream stays in step
+				items[i] = replyErr
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis serves the commands the store sends from a map, recording
+// them
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{listener: listener, password: password, values: map[string]string{}, ttls: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		var out string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == f.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "SELECT", args[0] == "SET":
+			if args[0] == "SET" {
+				f.values[args[1]] = args[2]
+				if len(args) == 5 {
+					f.ttls[args[1]] = args[4]
+				}
+			}
+			out = "+OK\r\n"
+		case args[0] == "GET":
+			if value, ok := f.values[args[1]]; ok {
+				out = "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			} else {
+				out = "$-1\r\n"
+			}
+		case args[0] == "DEL":
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := f.values[key]; ok {
+					delete(f.values, key)
+					deleted++
+				}
+			}
+			out = ":" + strconv.Itoa(deleted) + "\r\n"
+		default:
+			out = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t, "s3cret")
+	opts, err := ParseRedisURL("redis://:s3cret@" + server.listener.Addr().String() + "/2")
+	require.NoError(t, err)
+	opts.Prefix = "erp:"
+	store := NewRedis(opts)
+	defer store.Close()
+
+	_, ok, err := store.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "dashboard", []byte("{\"leads\":3}\r\n"), 90*time.Second))
+	value, ok, err := store.Get(ctx, "dashboard")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "{\"leads\":3}\r\n", string(value))
+
+	require.NoError(t, store.Delete(ctx, "dashboard", "missing"))
+	_, ok, err = store.Get(ctx, "dashboard")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// One connection is authenticated and selects the database once
+	server.mu.Lock()
+	assert.Equal(t, []string{
+		"AUTH s3cret", "SELECT 2", "GET erp:missing", "SET erp:dashboard {\"leads\":3}\r\n PX 90000",
+		"GET erp:dashboard", "DEL erp:dashboard erp:missing", "GET erp:dashboard",
+	}, server.commands)
+	server.mu.Unlock()
+
+	var replyErr RedisError
+	_, _, err = NewRedis(RedisOptions{Addr: server.listener.Addr().String(), Password: "wrong"}).Get(ctx, "dashboard")
+	assert.ErrorAs(t, err, &replyErr)
+}
+
+func TestParseRedisURL(t *testing.T) {
+	opts, err := ParseRedisURL("rediss://cache:pw@redis.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, RedisOptions{Addr: "redis.example.com:6379", Username: "cache", Password: "pw", TLS: true}, opts)
+
+	for _, bad := range []string{"http://localhost:6379", "redis://localhost:6379/db", "redis:///0"} {
+		_, err := ParseRedisURL(bad)
+		assert.Error(t, err, bad)
+	}
+}
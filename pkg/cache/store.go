@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is a cache of byte values with expiring keys, kept in memory or
+// shared between instances
+type Store interface {
+	// Get returns the value of key, and false when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of key for ttl; a zero ttl never expires
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// generationTTL is how long an organization's generation is kept. It only
+// needs to outlive most values: a lost generation is replaced, which
+// orphans the values stored under it.
+const generationTTL = 24 * time.Hour
+
+// OrgCache caches JSON values per organization in a Store. Keys are scoped
+// to a namespace and an organization, and carry the organization's
+// generation so that Invalidate drops all of its values at once, in any
+// store, by replacing the generation. Store failures are logged and treated
+// as misses, so a cache outage only makes reads slower.
+type OrgCache struct {
+	store     Store
+	namespace string
+	ttl       time.Duration
+	logger    *slog.Logger
+}
+
+// NewOrgCache creates a cache keeping values in store for ttl under
+// namespace
+func NewOrgCache(store Store, namespace string, ttl time.Duration, logger *slog.Logger) *OrgCache {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &OrgCache{store: store, namespace: namespace, ttl: ttl, logger: logger}
+}
+
+// Get decodes the organization's value of key into v and reports whether
+// it was cached
+func (c *OrgCache) Get(ctx context.Context, orgID uuid.UUID, key string, v interface{}) bool {
+	generation, ok := c.generation(ctx, orgID, false)
+	if !ok {
+		return false
+	}
+	data, found, err := c.store.Get(ctx, c.key(orgID, generation, key))
+	if err != nil {
+		c.logger.Warn("Failed to read cached value", "namespace", c.namespace, "key", key, "error", err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		c.logger.Warn("Failed to decode cached value", "namespace", c.namespace, "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+// Set caches the organization's value of key
+func (c *OrgCache) Set(ctx context.Context, orgID uuid.UUID, key string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.logger.Warn("Failed to encode value to cache", "namespace", c.namespace, "key", key, "error", err)
+		return
+	}
+	generation, ok := c.generation(ctx, orgID, true)
+	if !ok {
+		return
+	}
+	if err := c.store.Set(ctx, c.key(orgID, generation, key), data, c.ttl); err != nil {
+		c.logger.Warn("Failed to cache value", "namespace", c.namespace, "key", key, "error", err)
+	}
+}
+
+// Invalidate drops every cached value of the organization. Values computed
+// from data read before the change, but cached after it, are only served
+// until they expire.
+func (c *OrgCache) Invalidate(ctx context.Context, orgID uuid.UUID) {
+	if err := c.store.Delete(ctx, c.generationKey(orgID)); err != nil {
+		c.logger.Warn("Failed to invalidate cached values", "namespace", c.namespace, "organization_id", orgID, "error", err)
+	}
+}
+
+// generation returns the organization's current generation, starting a new
+// one when it has none and create is set
+func (c *OrgCache) generation(ctx context.Context, orgID uuid.UUID, create bool) (string, bool) {
+	key := c.generationKey(orgID)
+	data, found, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.logger.Warn("Failed to read cache generation", "namespace", c.namespace, "organization_id", orgID, "error", err)
+		return "", false
+	}
+	if found {
+		return string(data), true
+	}
+	if !create {
+		return "", false
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false
+	}
+	generation := hex.EncodeToString(buf)
+	if err := c.store.Set(ctx, key, []byte(generation), generationTTL); err != nil {
+		c.logger.Warn("Failed to start cache generation", "namespace", c.namespace, "organization_id", orgID, "error", err)
+		return "", false
+	}
+	return generation, true
+}
+
+func (c *OrgCache) generationKey(orgID uuid.UUID) string {
+	return c.namespace + ":" + orgID.String() + ":generation"
+}
+
+func (c *OrgCache) key(orgID uuid.UUID, generation, key string) string {
+	return c.namespace + ":" + orgID.String() + ":" + generation + ":" + key
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type dashboard struct {
+	Leads int `json:"leads"`
+}
+
+func TestOrgCacheScopesAndInvalidatesByOrganization(t *testing.T) {
+	ctx := context.Background()
+	c := NewOrgCache(NewLRU(0), "dashboards", time.Minute, nil)
+	acme, globex := uuid.New(), uuid.New()
+
+	var got dashboard
+	assert.False(t, c.Get(ctx, acme, "crm:7d", &got))
+
+	c.Set(ctx, acme, "crm:7d", dashboard{Leads: 3})
+	c.Set(ctx, globex, "crm:7d", dashboard{Leads: 5})
+	assert.True(t, c.Get(ctx, acme, "crm:7d", &got))
+	assert.Equal(t, 3, got.Leads)
+
+	c.Invalidate(ctx, acme)
+	assert.False(t, c.Get(ctx, acme, "crm:7d", &got))
+	assert.True(t, c.Get(ctx, globex, "crm:7d", &got))
+	assert.Equal(t, 5, got.Leads)
+
+	// A new generation starts with the next value
+	c.Set(ctx, acme, "crm:7d", dashboard{Leads: 4})
+	assert.True(t, c.Get(ctx, acme, "crm:7d", &got))
+	assert.Equal(t, 4, got.Leads)
+}
+
+// failingStore is a store whose server cannot be reached
+type failingStore struct{}
+
+func (failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Delete(ctx context.Context, keys ...string) error {
+	return errors.New("connection refused")
+}
+
+func TestOrgCacheTreatsStoreFailuresAsMisses(t *testing.T) {
+	ctx := context.Background()
+	c := NewOrgCache(failingStore{}, "dashboards", time.Minute, nil)
+	orgID := uuid.New()
+
+	c.Set(ctx, orgID, "crm:7d", dashboard{Leads: 3})
+	var got dashboard
+	assert.False(t, c.Get(ctx, orgID, "crm:7d", &got))
+	c.Invalidate(ctx, orgID)
+}
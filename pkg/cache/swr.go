@@ -0,0 +1,177 @@
+// Package cache provides caches for expensive read models, kept in process
+// or in a store shared between instances such as Redis.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default ages for a SWR cache
+const (
+	DefaultFreshFor      = time.Minute
+	DefaultServeStaleFor = time.Hour
+	DefaultLoadTimeout   = 2 * time.Minute
+)
+
+// Entry is a cached value and the time its computation started
+type Entry[T any] struct {
+	Value       T
+	GeneratedAt time.Time
+}
+
+// Loader computes the value for a key
+type Loader[T any] func(ctx context.Context) (T, error)
+
+// Options configures a SWR cache
+type Options struct {
+	// FreshFor is how long a value is served without refreshing it
+	FreshFor time.Duration
+	// ServeStaleFor is how long past FreshFor a value is still served
+	// while it refreshes in the background; older values are reloaded
+	// before being returned
+	ServeStaleFor time.Duration
+	// LoadTimeout bounds each load, which runs detached from the request
+	// that started it
+	LoadTimeout time.Duration
+}
+
+// SWR is a stale-while-revalidate cache. A fresh value is returned as is, a
+// stale one is returned immediately while a single background load replaces
+// it, and a missing or expired one is loaded while the caller waits.
+// Concurrent loads of the same key are shared.
+type SWR[T any] struct {
+	opts   Options
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*swrEntry[T]
+}
+
+type swrEntry[T any] struct {
+	entry  Entry[T]
+	loaded bool
+	load   *swrLoad[T]
+}
+
+// swrLoad is an in-flight load; done is closed once entry and err are set
+type swrLoad[T any] struct {
+	done  chan struct{}
+	entry Entry[T]
+	err   error
+}
+
+// NewSWR creates a stale-while-revalidate cache, using the defaults for
+// zero options
+func NewSWR[T any](opts Options, logger *slog.Logger) *SWR[T] {
+	if opts.FreshFor <= 0 {
+		opts.FreshFor = DefaultFreshFor
+	}
+	if opts.ServeStaleFor <= 0 {
+		opts.ServeStaleFor = DefaultServeStaleFor
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = DefaultLoadTimeout
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SWR[T]{
+		opts:    opts,
+		logger:  logger,
+		now:     time.Now,
+		entries: make(map[string]*swrEntry[T]),
+	}
+}
+
+// Get returns the value for key, loading it with load when it is missing,
+// expired or refresh is set. A forced refresh waits for a new value rather
+// than returning the cached one.
+func (c *SWR[T]) Get(ctx context.Context, key string, refresh bool, load Loader[T]) (Entry[T], error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &swrEntry[T]{}
+		c.entries[key] = e
+	}
+	if e.loaded && !refresh {
+		age := c.now().Sub(e.entry.GeneratedAt)
+		if age < c.opts.FreshFor+c.opts.ServeStaleFor {
+			if age >= c.opts.FreshFor && e.load == nil {
+				c.startLoad(ctx, key, e, load)
+			}
+			entry := e.entry
+			c.mu.Unlock()
+			return entry, nil
+		}
+	}
+	l := e.load
+	if l == nil {
+		l = c.startLoad(ctx, key, e, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return l.entry, l.err
+	case <-ctx.Done():
+		return Entry[T]{}, ctx.Err()
+	}
+}
+
+// Invalidate drops every cached value whose key starts with prefix, so the
+// next Get loads it again. Loads already in flight finish for the callers
+// waiting on them but are not cached.
+func (c *SWR[T]) Invalidate(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// startLoad loads key in the background; c.mu must be held. The load
+// outlives ctx so that a caller giving up does not fail others waiting.
+func (c *SWR[T]) startLoad(ctx context.Context, key string, e *swrEntry[T], load Loader[T]) *swrLoad[T] {
+	l := &swrLoad[T]{done: make(chan struct{})}
+	e.load = l
+	started := c.now()
+	loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.LoadTimeout)
+
+	go func() {
+		defer cancel()
+		value, err := load(loadCtx)
+
+		c.mu.Lock()
+		if err == nil {
+			l.entry = Entry[T]{Value: value, GeneratedAt: started}
+			e.entry, e.loaded = l.entry, true
+		} else {
+			l.err = err
+			if e.loaded {
+				c.logger.Warn("Failed to refresh cached value, serving stale value", "key", key, "error", err)
+			}
+		}
+		e.load = nil
+		c.prune()
+		c.mu.Unlock()
+		close(l.done)
+	}()
+	return l
+}
+
+// prune drops values too old to be served; c.mu must be held
+func (c *SWR[T]) prune() {
+	now := c.now()
+	for key, e := range c.entries {
+		if e.load == nil && (!e.loaded || now.Sub(e.entry.GeneratedAt) >= c.opts.FreshFor+c.opts.ServeStaleFor) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a settable clock for a SWR cache
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestSWR(t *testing.T) (*SWR[int], *testClock) {
+	clock := &testClock{now: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}
+	c := NewSWR[int](Options{FreshFor: time.Minute, ServeStaleFor: time.Hour}, nil)
+	c.now = clock.Now
+	return c, clock
+}
+
+// counter is a loader returning how many times it has been called
+type counter struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (l *counter) Load(ctx context.Context) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls++
+	return l.calls, nil
+}
+
+func TestSWRServesFreshValue(t *testing.T) {
+	c, clock := newTestSWR(t)
+	loader := &counter{}
+	ctx := context.Background()
+
+	entry, err := c.Get(ctx, "k", false, loader.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, entry.Value)
+	assert.Equal(t, clock.Now(), entry.GeneratedAt)
+
+	clock.Advance(30 * time.Second)
+	entry, err = c.Get(ctx, "k", false, loader.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, entry.Value)
+	assert.Equal(t, 1, loader.calls)
+}
+
+func TestSWRServesStaleValueWhileRefreshing(t *testing.T) {
+	c, clock := newTestSWR(t)
+	ctx := context.Background()
+	_, err := c.Get(ctx, "k", false, func(ctx context.Context) (int, error) { return 1, nil })
+	require.NoError(t, err)
+	clock.Advance(2 * time.Minute)
+
+	release := make(chan struct{})
+	refreshed := func(ctx context.Context) (int, error) {
+		<-release
+		return 2, nil
+	}
+
+	// Both callers get the stale value at once and share one refresh
+	for i := 0; i < 2; i++ {
+		entry, err := c.Get(ctx, "k", false, refreshed)
+		require.NoError(t, err)
+		assert.Equal(t, 1, entry.Value)
+	}
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		entry, err := c.Get(ctx, "k", false, refreshed)
+		return err == nil && entry.Value == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestSWRReloadsExpiredValue(t *testing.T) {
+	c, clock := newTestSWR(t)
+	loader := &counter{}
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "k", false, loader.Load)
+	require.NoError(t, err)
+	clock.Advance(2 * time.Hour)
+
+	entry, err := c.Get(ctx, "k", false, loader.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Value)
+}
+
+func TestSWRForcedRefreshWaitsForNewValue(t *testing.T) {
+	c, _ := newTestSWR(t)
+	loader := &counter{}
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "k", false, loader.Load)
+	require.NoError(t, err)
+
+	entry, err := c.Get(ctx, "k", true, loader.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Value)
+}
+
+func TestSWRKeepsStaleValueWhenRefreshFails(t *testing.T) {
+	c, clock := newTestSWR(t)
+	ctx := context.Background()
+	_, err := c.Get(ctx, "k", false, func(ctx context.Context) (int, error) { return 1, nil })
+	require.NoError(t, err)
+	clock.Advance(2 * time.Minute)
+
+	boom := errors.New("boom")
+	failing := func(ctx context.Context) (int, error) { return 0, boom }
+
+	_, err = c.Get(ctx, "k", true, failing)
+	assert.ErrorIs(t, err, boom)
+
+	entry, err := c.Get(ctx, "k", false, failing)
+	require.NoError(t, err)
+	assert.Equal(t, 1, entry.Value)
+}
+
+func TestSWRInvalidateByPrefix(t *testing.T) {
+	c, _ := newTestSWR(t)
+	ctx := context.Background()
+	a, b := &counter{}, &counter{}
+
+	_, err := c.Get(ctx, "org1:dashboard", false, a.Load)
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "org2:dashboard", false, b.Load)
+	require.NoError(t, err)
+
+	c.Invalidate("org1:")
+
+	entry, err := c.Get(ctx, "org1:dashboard", false, a.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Value)
+	entry, err = c.Get(ctx, "org2:dashboard", false, b.Load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, entry.Value)
+}
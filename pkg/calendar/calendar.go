@@ -0,0 +1,349 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MinutesPerDay is the largest valid Clock value, used as the end of a day
+const MinutesPerDay = 24 * 60
+
+// maxSearchDays bounds how far ahead the calendar looks for working time, so a
+// calendar whose remaining days are all holidays cannot loop forever
+const maxSearchDays = 2 * 366
+
+// Clock is a wall-clock time of day in minutes since midnight.
+// It is encoded in JSON as "HH:MM"; "24:00" marks the end of the day.
+type Clock int
+
+// ParseClock parses an "HH:MM" time of day
+func ParseClock(s string) (Clock, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h*60+m > MinutesPerDay {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return Clock(h*60 + m), nil
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *Clock) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseClock(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// on returns the instant of the clock time on the given day in loc.
+// time.Date normalises 24:00 to midnight of the next day and resolves DST gaps.
+func (c Clock) on(day time.Time, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(c)/60, int(c)%60, 0, 0, loc)
+}
+
+// Interval is a span of working time within a single day
+type Interval struct {
+	Start Clock `json:"start"`
+	End   Clock `json:"end"`
+}
+
+// WeeklyHours holds the working intervals of each weekday.
+// It is encoded in JSON as an object keyed by lowercase weekday name.
+type WeeklyHours map[time.Weekday][]Interval
+
+// Validate checks that every interval is non-empty, inside the day and that
+// intervals of the same day do not overlap
+func (h WeeklyHours) Validate() error {
+	total := 0
+	for day, intervals := range h {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", day)
+		}
+		sorted := sortedIntervals(intervals)
+		for i, iv := range sorted {
+			if iv.Start < 0 || iv.End > MinutesPerDay || iv.Start >= iv.End {
+				return fmt.Errorf("invalid interval %s-%s on %s", iv.Start, iv.End, strings.ToLower(day.String()))
+			}
+			if i > 0 && iv.Start < sorted[i-1].End {
+				return fmt.Errorf("overlapping intervals on %s", strings.ToLower(day.String()))
+			}
+		}
+		total += len(intervals)
+	}
+	if total == 0 {
+		return errors.New("calendar has no working hours")
+	}
+	return nil
+}
+
+func (h WeeklyHours) MarshalJSON() ([]byte, error) {
+	named := make(map[string][]Interval, len(h))
+	for day, intervals := range h {
+		named[strings.ToLower(day.String())] = intervals
+	}
+	return json.Marshal(named)
+}
+
+func (h *WeeklyHours) UnmarshalJSON(data []byte) error {
+	var named map[string][]Interval
+	if err := json.Unmarshal(data, &named); err != nil {
+		return err
+	}
+	hours := make(WeeklyHours, len(named))
+	for name, intervals := range named {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", name)
+		}
+		hours[day] = intervals
+	}
+	*h = hours
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func sortedIntervals(intervals []Interval) []Interval {
+	sorted := append([]Interval(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+// Holiday is a non-working calendar day.
+// Its date is encoded in JSON as "YYYY-MM-DD".
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
+
+func (h Holiday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}{Date: h.Date.Format(time.DateOnly), Name: h.Name})
+}
+
+func (h *Holiday) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	date, err := time.Parse(time.DateOnly, raw.Date)
+	if err != nil {
+		return fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", raw.Date)
+	}
+	h.Date, h.Name = date, raw.Name
+	return nil
+}
+
+// Calendar answers working-time questions for a set of weekly business hours
+// and holidays, evaluated in the calendar's time zone
+type Calendar struct {
+	location *time.Location
+	hours    [7][]Interval
+	holidays map[string]string
+}
+
+// New builds a calendar. Holidays are matched by calendar date in loc.
+func New(loc *time.Location, hours WeeklyHours, holidays []Holiday) (*Calendar, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if err := hours.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{location: loc, holidays: make(map[string]string, len(holidays))}
+	for day, intervals := range hours {
+		c.hours[day] = sortedIntervals(intervals)
+	}
+	for _, h := range holidays {
+		c.holidays[h.Date.Format(time.DateOnly)] = h.Name
+	}
+	return c, nil
+}
+
+// Always returns a calendar that is open around the clock in UTC. It is the
+// fallback for organizations that have not configured business hours.
+func Always() *Calendar {
+	c := &Calendar{location: time.UTC, holidays: map[string]string{}}
+	for day := range c.hours {
+		c.hours[day] = []Interval{{Start: 0, End: MinutesPerDay}}
+	}
+	return c
+}
+
+// Location returns the calendar's time zone
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// Holiday reports whether the calendar day containing t is a holiday
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[t.In(c.location).Format(time.DateOnly)]
+	return name, ok
+}
+
+// IsOpen reports whether t falls inside working hours
+func (c *Calendar) IsOpen(t time.Time) bool {
+	next, ok := c.NextOpen(t)
+	return ok && next.Equal(t)
+}
+
+// NextOpen returns t when it is inside working hours, otherwise the start of
+// the next working interval. It returns false when no working time exists
+// within the search horizon.
+func (c *Calendar) NextOpen(t time.Time) (time.Time, bool) {
+	found := false
+	var next time.Time
+	c.walk(t, func(start, end time.Time) bool {
+		next, found = start, true
+		return false
+	})
+	if !found {
+		return t, false
+	}
+	return next, true
+}
+
+// Add returns the instant reached after d of working time has elapsed from t.
+// Non-working time is skipped, so a deadline set outside business hours starts
+// counting at the next opening. If the horizon runs out, the remainder is
+// added as wall-clock time.
+func (c *Calendar) Add(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t
+	}
+	remaining := d
+	result := t
+	c.walk(t, func(start, end time.Time) bool {
+		span := end.Sub(start)
+		if remaining <= span {
+			result = start.Add(remaining)
+			remaining = 0
+			return false
+		}
+		remaining -= span
+		result = end
+		return true
+	})
+	return result.Add(remaining)
+}
+
+// Between returns the working time elapsed between from and to
+func (c *Calendar) Between(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	var total time.Duration
+	c.walk(from, func(start, end time.Time) bool {
+		if !start.Before(to) {
+			return false
+		}
+		if end.After(to) {
+			end = to
+		}
+		total += end.Sub(start)
+		return true
+	})
+	return total
+}
+
+// Span is a period of time, such as a meeting keeping someone busy
+type Span struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Overlaps reports whether the span shares any time with start..end
+func (s Span) Overlaps(start, end time.Time) bool {
+	return s.Start.Before(end) && s.End.After(start)
+}
+
+// Slots returns the starts, from from until before to, of the slots of the
+// given length that fit in a working interval and overlap none of the busy
+// spans. Slots start on multiples of step from midnight in the calendar's
+// time zone, so a 30 minute step offers 09:00, 09:30 and so on.
+func (c *Calendar) Slots(from, to time.Time, length, step time.Duration, busy []Span) []time.Time {
+	slots := []time.Time{}
+	if length <= 0 || step <= 0 || !to.After(from) {
+		return slots
+	}
+	c.walk(from, func(start, end time.Time) bool {
+		if !start.Before(to) {
+			return false
+		}
+		local := start.In(c.location)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+		if offset := start.Sub(midnight) % step; offset != 0 {
+			start = start.Add(step - offset)
+		}
+		for slot := start; slot.Before(to) && !slot.Add(length).After(end); slot = slot.Add(step) {
+			free := true
+			for _, b := range busy {
+				if b.Overlaps(slot, slot.Add(length)) {
+					free = false
+					break
+				}
+			}
+			if free {
+				slots = append(slots, slot)
+			}
+		}
+		return true
+	})
+	return slots
+}
+
+// walk calls fn with each working interval from t onwards, clipped to start no
+// earlier than t, until fn returns false or the search horizon is reached
+func (c *Calendar) walk(t time.Time, fn func(start, end time.Time) bool) {
+	local := t.In(c.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+
+	for i := 0; i < maxSearchDays; i++ {
+		if _, holiday := c.holidays[day.Format(time.DateOnly)]; !holiday {
+			for _, iv := range c.hours[day.Weekday()] {
+				start, end := iv.Start.on(day, c.location), iv.End.on(day, c.location)
+				if !end.After(t) {
+					continue
+				}
+				if start.Before(t) {
+					start = t
+				}
+				if !fn(start, end) {
+					return
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.location)
+	}
+}
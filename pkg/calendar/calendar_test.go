@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func officeHours() WeeklyHours {
+	day := []Interval{{Start: 9 * 60, End: 12 * 60}, {Start: 13 * 60, End: 17 * 60}}
+	return WeeklyHours{
+		time.Monday:    day,
+		time.Tuesday:   day,
+		time.Wednesday: day,
+		time.Thursday:  day,
+		time.Friday:    day,
+	}
+}
+
+func newOfficeCalendar(t *testing.T, holidays ...Holiday) (*Calendar, *time.Location) {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	cal, err := New(loc, officeHours(), holidays)
+	require.NoError(t, err)
+	return cal, loc
+}
+
+func TestIsOpen(t *testing.T) {
+	cal, loc := newOfficeCalendar(t)
+
+	// 2025-03-03 is a Monday
+	assert.True(t, cal.IsOpen(time.Date(2025, 3, 3, 9, 0, 0, 0, loc)))
+	assert.False(t, cal.IsOpen(time.Date(2025, 3, 3, 12, 30, 0, 0, loc)), "lunch break")
+	assert.False(t, cal.IsOpen(time.Date(2025, 3, 3, 17, 0, 0, 0, loc)), "end is exclusive")
+	assert.False(t, cal.IsOpen(time.Date(2025, 3, 8, 10, 0, 0, 0, loc)), "saturday")
+	// 08:30 UTC is 09:30 in Paris
+	assert.True(t, cal.IsOpen(time.Date(2025, 3, 3, 8, 30, 0, 0, time.UTC)))
+}
+
+func TestNextOpenSkipsWeekendAndHolidays(t *testing.T) {
+	cal, loc := newOfficeCalendar(t, Holiday{Date: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Name: "Closure"})
+
+	next, ok := cal.NextOpen(time.Date(2025, 3, 7, 18, 0, 0, 0, loc))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 11, 9, 0, 0, 0, loc), next)
+
+	name, holiday := cal.Holiday(time.Date(2025, 3, 10, 15, 0, 0, 0, loc))
+	assert.True(t, holiday)
+	assert.Equal(t, "Closure", name)
+}
+
+func TestAddSkipsNonWorkingTime(t *testing.T) {
+	cal, loc := newOfficeCalendar(t)
+
+	// Friday 16:00 + 2h: one hour on Friday, one on Monday morning
+	due := cal.Add(time.Date(2025, 3, 7, 16, 0, 0, 0, loc), 2*time.Hour)
+	assert.Equal(t, time.Date(2025, 3, 10, 10, 0, 0, 0, loc), due)
+
+	// Monday 11:00 + 2h crosses the lunch break
+	due = cal.Add(time.Date(2025, 3, 3, 11, 0, 0, 0, loc), 2*time.Hour)
+	assert.Equal(t, time.Date(2025, 3, 3, 14, 0, 0, 0, loc), due)
+
+	// Starting outside hours counts from the next opening
+	due = cal.Add(time.Date(2025, 3, 3, 6, 0, 0, 0, loc), 30*time.Minute)
+	assert.Equal(t, time.Date(2025, 3, 3, 9, 30, 0, 0, loc), due)
+}
+
+func TestAddAcrossDSTChange(t *testing.T) {
+	cal, loc := newOfficeCalendar(t)
+
+	// Clocks go forward on Sunday 2025-03-30; working hours stay 09:00 local
+	due := cal.Add(time.Date(2025, 3, 28, 16, 0, 0, 0, loc), 2*time.Hour)
+	assert.Equal(t, time.Date(2025, 3, 31, 10, 0, 0, 0, loc), due)
+}
+
+func TestBetween(t *testing.T) {
+	cal, loc := newOfficeCalendar(t)
+
+	from := time.Date(2025, 3, 7, 11, 0, 0, 0, loc)
+	to := time.Date(2025, 3, 10, 10, 0, 0, 0, loc)
+	// Friday 11-12 and 13-17, Monday 9-10
+	assert.Equal(t, 6*time.Hour, cal.Between(from, to))
+	assert.Zero(t, cal.Between(to, from))
+}
+
+func TestSlotsSkipBusyAndClosedTime(t *testing.T) {
+	cal, loc := newOfficeCalendar(t)
+
+	// Monday from 10:10 to 14:00, with a meeting from 11:00 to 11:30
+	from := time.Date(2025, 3, 3, 10, 10, 0, 0, loc)
+	to := time.Date(2025, 3, 3, 14, 0, 0, 0, loc)
+	busy := []Span{{Start: time.Date(2025, 3, 3, 11, 0, 0, 0, loc), End: time.Date(2025, 3, 3, 11, 30, 0, 0, loc)}}
+
+	at := func(h, m int) time.Time { return time.Date(2025, 3, 3, h, m, 0, 0, loc) }
+	assert.Equal(t, []time.Time{at(10, 30), at(11, 30), at(13, 0), at(13, 30)},
+		cal.Slots(from, to, 30*time.Minute, 30*time.Minute, busy))
+	// An hour long slot must also end before the lunch break
+	assert.Equal(t, []time.Time{at(13, 0), at(13, 30)}, cal.Slots(from, to, time.Hour, 30*time.Minute, busy))
+	assert.Empty(t, cal.Slots(to, from, time.Hour, 30*time.Minute, nil))
+}
+
+func TestAlways(t *testing.T) {
+	cal := Always()
+	start := time.Date(2025, 3, 8, 23, 0, 0, 0, time.UTC)
+
+	assert.True(t, cal.IsOpen(start))
+	assert.Equal(t, start.Add(3*time.Hour), cal.Add(start, 3*time.Hour))
+	assert.Equal(t, 3*time.Hour, cal.Between(start, start.Add(3*time.Hour)))
+}
+
+func TestWeeklyHoursValidate(t *testing.T) {
+	_, err := New(time.UTC, WeeklyHours{}, nil)
+	assert.Error(t, err)
+
+	_, err = New(time.UTC, WeeklyHours{time.Monday: {{Start: 600, End: 540}}}, nil)
+	assert.Error(t, err)
+
+	_, err = New(time.UTC, WeeklyHours{time.Monday: {{Start: 540, End: 720}, {Start: 700, End: 800}}}, nil)
+	assert.Error(t, err)
+}
+
+func TestWeeklyHoursJSON(t *testing.T) {
+	var hours WeeklyHours
+	err := json.Unmarshal([]byte(`{"monday":[{"start":"09:00","end":"17:30"}],"saturday":[{"start":"10:00","end":"24:00"}]}`), &hours)
+	require.NoError(t, err)
+	assert.Equal(t, []Interval{{Start: 540, End: 1050}}, hours[time.Monday])
+	assert.Equal(t, Clock(MinutesPerDay), hours[time.Saturday][0].End)
+
+	data, err := json.Marshal(hours)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"monday":[{"start":"09:00","end":"17:30"}],"saturday":[{"start":"10:00","end":"24:00"}]}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"funday":[]}`), &hours))
+	assert.Error(t, json.Unmarshal([]byte(`{"monday":[{"start":"9am","end":"17:00"}]}`), &hours))
+}
+
+func TestHolidayJSON(t *testing.T) {
+	var h Holiday
+	require.NoError(t, json.Unmarshal([]byte(`{"date":"2025-12-25","name":"Christmas"}`), &h))
+	assert.Equal(t, time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC), h.Date)
+
+	data, err := json.Marshal(h)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"date":"2025-12-25","name":"Christmas"}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"date":"25/12/2025"}`), &h))
+}
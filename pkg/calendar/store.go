@@ -0,0 +1,198 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Store loads business calendars configured per organization and team
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a calendar store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Resolve returns the calendar that applies to a team. A team without its own
+// calendar uses the organization default; an organization without a default
+// is treated as always open.
+func (s *Store) Resolve(ctx context.Context, orgID uuid.UUID, teamID *uuid.UUID) (*Calendar, error) {
+	query := `
+		SELECT c.timezone, c.weekly_hours, c.holiday_set_id
+		FROM business_calendars c
+		WHERE c.organization_id = $1
+			AND c.active = true
+			AND ((c.team_id IS NOT NULL AND c.team_id = $2) OR (c.team_id IS NULL AND c.is_default = true))
+		ORDER BY c.team_id IS NULL
+		LIMIT 1
+	`
+
+	var timezone string
+	var hoursJSON []byte
+	var holidaySetID *uuid.UUID
+	err := s.db.QueryRowContext(ctx, query, orgID, teamID).Scan(&timezone, &hoursJSON, &holidaySetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Always(), nil
+		}
+		return nil, fmt.Errorf("failed to load business calendar: %w", err)
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar timezone %q: %w", timezone, err)
+	}
+
+	var hours WeeklyHours
+	if err := json.Unmarshal(hoursJSON, &hours); err != nil {
+		return nil, fmt.Errorf("invalid calendar weekly hours: %w", err)
+	}
+
+	var holidays []Holiday
+	if holidaySetID != nil {
+		holidays, err = s.holidays(ctx, *holidaySetID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return New(loc, hours, holidays)
+}
+
+// ResolveUsers returns the working hours of the users who have some, keyed
+// by user. Users without working hours are left out; they work whenever
+// their team's business calendar is open. A user without their own holiday
+// set observes the holidays of the organization default calendar.
+func (s *Store) ResolveUsers(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*Calendar, error) {
+	query := `
+		SELECT w.user_id, w.timezone, w.weekly_hours, COALESCE(w.holiday_set_id, (
+			SELECT c.holiday_set_id FROM business_calendars c
+			WHERE c.organization_id = w.organization_id
+				AND c.team_id IS NULL AND c.is_default = true AND c.active = true
+		))
+		FROM user_working_hours w
+		WHERE w.organization_id = $1 AND w.user_id = ANY($2)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user working hours: %w", err)
+	}
+	defer rows.Close()
+
+	type userHours struct {
+		userID       uuid.UUID
+		timezone     string
+		hoursJSON    []byte
+		holidaySetID *uuid.UUID
+	}
+	var found []userHours
+	for rows.Next() {
+		var h userHours
+		if err := rows.Scan(&h.userID, &h.timezone, &h.hoursJSON, &h.holidaySetID); err != nil {
+			return nil, fmt.Errorf("failed to scan user working hours: %w", err)
+		}
+		found = append(found, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user working hours: %w", err)
+	}
+
+	calendars := make(map[uuid.UUID]*Calendar, len(found))
+	sets := make(map[uuid.UUID][]Holiday)
+	for _, h := range found {
+		loc, err := time.LoadLocation(h.timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working hours timezone %q: %w", h.timezone, err)
+		}
+		var hours WeeklyHours
+		if err := json.Unmarshal(h.hoursJSON, &hours); err != nil {
+			return nil, fmt.Errorf("invalid user weekly hours: %w", err)
+		}
+
+		var holidays []Holiday
+		if h.holidaySetID != nil {
+			var ok bool
+			if holidays, ok = sets[*h.holidaySetID]; !ok {
+				if holidays, err = s.holidays(ctx, *h.holidaySetID); err != nil {
+					return nil, err
+				}
+				sets[*h.holidaySetID] = holidays
+			}
+		}
+
+		cal, err := New(loc, hours, holidays)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working hours of user %s: %w", h.userID, err)
+		}
+		calendars[h.userID] = cal
+	}
+
+	return calendars, nil
+}
+
+func (s *Store) holidays(ctx context.Context, setID uuid.UUID) ([]Holiday, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT holiday_date, name FROM business_holidays WHERE holiday_set_id = $1`, setID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+	defer rows.Close()
+
+	var holidays []Holiday
+	for rows.Next() {
+		var h Holiday
+		if err := rows.Scan(&h.Date, &h.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		holidays = append(holidays, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating holidays: %w", err)
+	}
+
+	return holidays, nil
+}
+
+// Busy returns, per user, the calendar events that are not cancelled and
+// overlap from..to, earliest first
+func (s *Store) Busy(ctx context.Context, orgID uuid.UUID, userIDs []uuid.UUID, from, to time.Time) (map[uuid.UUID][]Span, error) {
+	query := `
+		SELECT user_id, starts_at, ends_at
+		FROM calendar_events
+		WHERE organization_id = $1 AND user_id = ANY($2) AND status <> 'cancelled'
+			AND starts_at < $4 AND ends_at > $3
+		ORDER BY starts_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, pq.Array(userIDs), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar events: %w", err)
+	}
+	defer rows.Close()
+
+	busy := make(map[uuid.UUID][]Span, len(userIDs))
+	for rows.Next() {
+		var userID uuid.UUID
+		var span Span
+		if err := rows.Scan(&userID, &span.Start, &span.End); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar event: %w", err)
+		}
+		busy[userID] = append(busy[userID], span)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar events: %w", err)
+	}
+
+	return busy, nil
+}
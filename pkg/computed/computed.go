@@ -0,0 +1,208 @@
+// Package computed compiles formula and rollup field definitions into SQL so
+// that computed values are evaluated by the database on read and can be
+// selected, filtered and aggregated like regular columns.
+package computed
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind distinguishes formula fields from rollup fields
+type Kind string
+
+const (
+	// KindFormula is arithmetic over the record's own numeric fields
+	KindFormula Kind = "formula"
+	// KindRollup aggregates a numeric field over child records
+	KindRollup Kind = "rollup"
+)
+
+// Aggregate is the function a rollup applies to child values
+type Aggregate string
+
+const (
+	AggregateSum   Aggregate = "sum"
+	AggregateCount Aggregate = "count"
+	AggregateAvg   Aggregate = "avg"
+	AggregateMin   Aggregate = "min"
+	AggregateMax   Aggregate = "max"
+)
+
+// ErrUnknownField is returned when a filter or report names a computed field that is not defined
+var ErrUnknownField = errors.New("unknown computed field")
+
+// MaxFieldsPerEntity caps computed fields per entity; every field is one
+// pair of arguments to jsonb_build_object, which accepts at most 100
+const MaxFieldsPerEntity = 50
+
+var (
+	namePattern        = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	filterValuePattern = regexp.MustCompile(`^[A-Za-z0-9_ -]{1,50}$`)
+)
+
+// RollupCondition restricts a rollup to child records whose field has one of the values
+type RollupCondition struct {
+	Field  string   `json:"field"`
+	Values []string `json:"values"`
+}
+
+// Rollup aggregates a field over a relation's child records
+type Rollup struct {
+	Relation  string            `json:"relation"`
+	Aggregate Aggregate         `json:"aggregate"`
+	Field     string            `json:"field,omitempty"`
+	Where     []RollupCondition `json:"where,omitempty"`
+}
+
+// Definition describes a computed field of an entity
+type Definition struct {
+	Entity  string  `json:"entity"`
+	Name    string  `json:"name"`
+	Label   string  `json:"label"`
+	Kind    Kind    `json:"kind"`
+	Formula string  `json:"formula,omitempty"`
+	Rollup  *Rollup `json:"rollup,omitempty"`
+}
+
+// Field is a compiled computed field. SQL references the entity's table by
+// name, so it is valid in any query that selects FROM that table unaliased.
+type Field struct {
+	Name string
+	SQL  string
+}
+
+// Compile validates a definition against its entity's schema and returns the SQL expression
+func Compile(def Definition) (Field, error) {
+	schema, ok := Schemas[def.Entity]
+	if !ok {
+		return Field{}, fmt.Errorf("entity %q does not support computed fields", def.Entity)
+	}
+	if !namePattern.MatchString(def.Name) {
+		return Field{}, fmt.Errorf("invalid name %q: use 1-50 lowercase letters, digits or underscores, starting with a letter", def.Name)
+	}
+
+	var sql string
+	var err error
+	switch def.Kind {
+	case KindFormula:
+		if def.Rollup != nil {
+			return Field{}, fmt.Errorf("formula fields do not take a rollup")
+		}
+		sql, err = compileFormula(schema, def.Formula)
+	case KindRollup:
+		if def.Formula != "" {
+			return Field{}, fmt.Errorf("rollup fields do not take a formula")
+		}
+		if def.Rollup == nil {
+			return Field{}, fmt.Errorf("rollup is required")
+		}
+		sql, err = compileRollup(schema, *def.Rollup)
+	default:
+		return Field{}, fmt.Errorf("unknown kind %q", def.Kind)
+	}
+	if err != nil {
+		return Field{}, err
+	}
+	return Field{Name: def.Name, SQL: sql}, nil
+}
+
+func compileRollup(schema Schema, rollup Rollup) (string, error) {
+	rel, ok := schema.Relations[rollup.Relation]
+	if !ok {
+		return "", fmt.Errorf("unknown relation %q", rollup.Relation)
+	}
+
+	var value string
+	if rollup.Aggregate == AggregateCount {
+		if rollup.Field != "" {
+			return "", fmt.Errorf("count rollups do not take a field")
+		}
+		value = "COUNT(*)"
+	} else {
+		column, ok := rel.Fields[rollup.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown field %q on relation %q", rollup.Field, rollup.Relation)
+		}
+		switch rollup.Aggregate {
+		case AggregateSum:
+			// A parent without children totals zero rather than NULL
+			value = "COALESCE(SUM(rollup_child." + column + "), 0)"
+		case AggregateAvg, AggregateMin, AggregateMax:
+			value = strings.ToUpper(string(rollup.Aggregate)) + "(rollup_child." + column + ")"
+		default:
+			return "", fmt.Errorf("unknown aggregate %q", rollup.Aggregate)
+		}
+	}
+
+	conditions := []string{
+		"rollup_child." + rel.ForeignKey + " = " + schema.Table + ".id",
+		"rollup_child.organization_id = " + schema.Table + ".organization_id",
+		"rollup_child.deleted_at IS NULL",
+	}
+	for _, c := range rollup.Where {
+		column, ok := rel.Filters[c.Field]
+		if !ok {
+			return "", fmt.Errorf("relation %q cannot be filtered on %q", rollup.Relation, c.Field)
+		}
+		if len(c.Values) == 0 {
+			return "", fmt.Errorf("condition on %q needs at least one value", c.Field)
+		}
+		literals := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			// Values are restricted to a safe character set and quoted as literals
+			if !filterValuePattern.MatchString(v) {
+				return "", fmt.Errorf("invalid value %q for %q", v, c.Field)
+			}
+			literals[i] = "'" + v + "'"
+		}
+		conditions = append(conditions, "rollup_child."+column+" IN ("+strings.Join(literals, ", ")+")")
+	}
+
+	return "(SELECT " + value + " FROM " + rel.Table + " rollup_child WHERE " + strings.Join(conditions, " AND ") + ")", nil
+}
+
+// SelectSQL returns a single jsonb expression holding every field's value by
+// name, so callers add one column to their select list regardless of how
+// many computed fields are defined
+func SelectSQL(fields []Field) string {
+	if len(fields) == 0 {
+		return "'{}'::jsonb"
+	}
+	parts := make([]string, 0, len(fields)*2)
+	for _, f := range fields {
+		parts = append(parts, "'"+f.Name+"'", f.SQL)
+	}
+	return "jsonb_build_object(" + strings.Join(parts, ", ") + ")"
+}
+
+// Lookup returns the compiled field with the given name
+func Lookup(fields []Field, name string) (Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// GroupColumn returns the column an entity's summaries may be grouped by
+func GroupColumn(entity, groupBy string) (string, error) {
+	schema, ok := Schemas[entity]
+	if !ok {
+		return "", fmt.Errorf("entity %q does not support computed fields", entity)
+	}
+	column, ok := schema.GroupBy[groupBy]
+	if !ok {
+		options := make([]string, 0, len(schema.GroupBy))
+		for k := range schema.GroupBy {
+			options = append(options, k)
+		}
+		sort.Strings(options)
+		return "", fmt.Errorf("cannot group %s by %q; use one of %s", entity, groupBy, strings.Join(options, ", "))
+	}
+	return column, nil
+}
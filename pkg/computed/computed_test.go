@@ -0,0 +1,109 @@
+package computed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFormula(t *testing.T) {
+	field, err := Compile(Definition{
+		Entity:  "lead",
+		Name:    "weighted_revenue",
+		Kind:    KindFormula,
+		Formula: "coalesce(expected_revenue, 0) * Probability / 100",
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"((COALESCE(leads.expected_revenue::numeric, 0::numeric) * leads.probability::numeric) / NULLIF(100::numeric, 0))",
+		field.SQL)
+
+	field, err = Compile(Definition{Entity: "lead", Name: "rounded", Kind: KindFormula, Formula: "round(-recurring_revenue * 12, 2)"})
+	require.NoError(t, err)
+	assert.Equal(t, "ROUND(((-leads.recurring_revenue::numeric) * 12::numeric), 2)", field.SQL)
+}
+
+func TestCompileFormulaRejectsInvalidInput(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":      "expected_revenue * discount",
+		"sql injection":      "expected_revenue; DROP TABLE leads",
+		"quoted string":      "'1'",
+		"unbalanced":         "(expected_revenue * 2",
+		"trailing operator":  "expected_revenue *",
+		"trailing tokens":    "expected_revenue probability",
+		"bad number":         "1.2.3",
+		"round precision":    "round(expected_revenue, probability)",
+		"too few arguments":  "coalesce(expected_revenue)",
+		"too many arguments": "abs(expected_revenue, probability)",
+		"empty":              "  ",
+	}
+	for name, formula := range cases {
+		_, err := Compile(Definition{Entity: "lead", Name: "f", Kind: KindFormula, Formula: formula})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCompileRollup(t *testing.T) {
+	field, err := Compile(Definition{
+		Entity: "contact",
+		Name:   "open_pipeline",
+		Kind:   KindRollup,
+		Rollup: &Rollup{
+			Relation:  "leads",
+			Aggregate: AggregateSum,
+			Field:     "expected_revenue",
+			Where:     []RollupCondition{{Field: "status", Values: []string{"new", "in_progress"}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		"(SELECT COALESCE(SUM(rollup_child.expected_revenue), 0) FROM leads rollup_child"+
+			" WHERE rollup_child.contact_id = contacts.id AND rollup_child.organization_id = contacts.organization_id"+
+			" AND rollup_child.deleted_at IS NULL AND rollup_child.status IN ('new', 'in_progress'))",
+		field.SQL)
+
+	field, err = Compile(Definition{Entity: "contact", Name: "order_count", Kind: KindRollup, Rollup: &Rollup{Relation: "sales_orders", Aggregate: AggregateCount}})
+	require.NoError(t, err)
+	assert.Contains(t, field.SQL, "SELECT COUNT(*) FROM sales_orders rollup_child WHERE rollup_child.partner_id = contacts.id")
+}
+
+func TestCompileRollupRejectsInvalidInput(t *testing.T) {
+	cases := map[string]Rollup{
+		"unknown relation":   {Relation: "tickets", Aggregate: AggregateSum, Field: "amount_total"},
+		"unknown field":      {Relation: "invoices", Aggregate: AggregateSum, Field: "discount"},
+		"count with field":   {Relation: "invoices", Aggregate: AggregateCount, Field: "amount_total"},
+		"unknown aggregate":  {Relation: "invoices", Aggregate: "median", Field: "amount_total"},
+		"unfilterable field": {Relation: "invoices", Aggregate: AggregateCount, Where: []RollupCondition{{Field: "name", Values: []string{"x"}}}},
+		"no values":          {Relation: "invoices", Aggregate: AggregateCount, Where: []RollupCondition{{Field: "state"}}},
+		"quoted value":       {Relation: "invoices", Aggregate: AggregateCount, Where: []RollupCondition{{Field: "state", Values: []string{"x') OR ('1"}}}},
+	}
+	for name, rollup := range cases {
+		rollup := rollup
+		_, err := Compile(Definition{Entity: "contact", Name: "r", Kind: KindRollup, Rollup: &rollup})
+		assert.Error(t, err, name)
+	}
+
+	_, err := Compile(Definition{Entity: "lead", Name: "r", Kind: KindRollup, Rollup: &Rollup{Relation: "leads", Aggregate: AggregateCount}})
+	assert.Error(t, err, "leads have no rollup relations")
+
+	_, err = Compile(Definition{Entity: "invoice", Name: "r", Kind: KindFormula, Formula: "1"})
+	assert.Error(t, err, "unsupported entity")
+
+	_, err = Compile(Definition{Entity: "lead", Name: "Weighted Revenue", Kind: KindFormula, Formula: "1"})
+	assert.Error(t, err, "invalid name")
+}
+
+func TestSelectSQL(t *testing.T) {
+	assert.Equal(t, "'{}'::jsonb", SelectSQL(nil))
+	assert.Equal(t, "jsonb_build_object('a', 1, 'b', 2)", SelectSQL([]Field{{Name: "a", SQL: "1"}, {Name: "b", SQL: "2"}}))
+}
+
+func TestGroupColumn(t *testing.T) {
+	column, err := GroupColumn("lead", "stage_id")
+	require.NoError(t, err)
+	assert.Equal(t, "stage_id", column)
+
+	_, err = GroupColumn("lead", "name")
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package computed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxFormulaLength bounds formulas so compiled SQL stays small
+const maxFormulaLength = 500
+
+// functions maps formula functions to SQL and their minimum and maximum
+// argument counts (-1 for variadic)
+var functions = map[string]struct {
+	sql      string
+	min, max int
+}{
+	"coalesce": {sql: "COALESCE", min: 2, max: -1},
+	"min":      {sql: "LEAST", min: 2, max: -1},
+	"max":      {sql: "GREATEST", min: 2, max: -1},
+	"abs":      {sql: "ABS", min: 1, max: 1},
+	"round":    {sql: "ROUND", min: 1, max: 2},
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/(),", c):
+			tokens = append(tokens, token{kind: tokenOp, text: string(c), pos: i})
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(src[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// formulaParser is a recursive descent parser that emits SQL directly:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | field | function "(" expr { "," expr } ")" | "(" expr ")"
+type formulaParser struct {
+	tokens []token
+	pos    int
+	schema Schema
+}
+
+func (p *formulaParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *formulaParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *formulaParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *formulaParser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d", op, t.pos)
+	}
+	return nil
+}
+
+func (p *formulaParser) expr() (string, error) {
+	left, err := p.term()
+	if err != nil {
+		return "", err
+	}
+	for {
+		switch {
+		case p.accept("+"):
+			right, err := p.term()
+			if err != nil {
+				return "", err
+			}
+			left = "(" + left + " + " + right + ")"
+		case p.accept("-"):
+			right, err := p.term()
+			if err != nil {
+				return "", err
+			}
+			left = "(" + left + " - " + right + ")"
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *formulaParser) term() (string, error) {
+	left, err := p.unary()
+	if err != nil {
+		return "", err
+	}
+	for {
+		switch {
+		case p.accept("*"):
+			right, err := p.unary()
+			if err != nil {
+				return "", err
+			}
+			left = "(" + left + " * " + right + ")"
+		case p.accept("/"):
+			right, err := p.unary()
+			if err != nil {
+				return "", err
+			}
+			// Division by zero yields NULL rather than failing the whole query
+			left = "(" + left + " / NULLIF(" + right + ", 0))"
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *formulaParser) unary() (string, error) {
+	if p.accept("-") {
+		operand, err := p.unary()
+		if err != nil {
+			return "", err
+		}
+		return "(-" + operand + ")", nil
+	}
+	return p.primary()
+}
+
+func (p *formulaParser) primary() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			return "", fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return t.text + "::numeric", nil
+
+	case tokenIdent:
+		if fn, ok := functions[t.text]; ok && p.peek().kind == tokenOp && p.peek().text == "(" {
+			return p.call(t, fn.sql, fn.min, fn.max)
+		}
+		column, ok := p.schema.Fields[t.text]
+		if !ok {
+			return "", fmt.Errorf("unknown field %q at position %d", t.text, t.pos)
+		}
+		return p.schema.Table + "." + column + "::numeric", nil
+
+	case tokenOp:
+		if t.text == "(" {
+			inner, err := p.expr()
+			if err != nil {
+				return "", err
+			}
+			if err := p.expect(")"); err != nil {
+				return "", err
+			}
+			return inner, nil
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return "", fmt.Errorf("unexpected end of formula")
+	}
+	return "", fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *formulaParser) call(name token, sqlName string, minArgs, maxArgs int) (string, error) {
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+
+	var args []string
+	for {
+		if name.text == "round" && len(args) == 1 {
+			// The precision must be a literal integer
+			t := p.next()
+			n, err := strconv.Atoi(t.text)
+			if t.kind != tokenNumber || err != nil || n < 0 || n > 10 {
+				return "", fmt.Errorf("round precision must be an integer between 0 and 10 at position %d", t.pos)
+			}
+			args = append(args, strconv.Itoa(n))
+		} else {
+			arg, err := p.expr()
+			if err != nil {
+				return "", err
+			}
+			args = append(args, arg)
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return "", fmt.Errorf("wrong number of arguments to %s at position %d", name.text, name.pos)
+	}
+	return sqlName + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// compileFormula translates an arithmetic formula over the schema's numeric
+// fields into a SQL expression
+func compileFormula(schema Schema, formula string) (string, error) {
+	if strings.TrimSpace(formula) == "" {
+		return "", fmt.Errorf("formula is required")
+	}
+	if len(formula) > maxFormulaLength {
+		return "", fmt.Errorf("formula must be at most %d characters", maxFormulaLength)
+	}
+
+	tokens, err := tokenize(formula)
+	if err != nil {
+		return "", err
+	}
+
+	p := &formulaParser{tokens: tokens, schema: schema}
+	sql, err := p.expr()
+	if err != nil {
+		return "", err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return "", fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return sql, nil
+}
@@ -0,0 +1,98 @@
+package computed
+
+// Relation is a child table that rollups of an entity can aggregate over
+type Relation struct {
+	Table string
+	// ForeignKey is the child column referencing the parent record's id
+	ForeignKey string
+	// Fields are the numeric child columns that can be aggregated
+	Fields map[string]string
+	// Filters are the child columns a rollup may restrict on
+	Filters map[string]string
+}
+
+// Schema whitelists what computed fields of an entity may reference
+type Schema struct {
+	Entity string
+	Table  string
+	// Fields are the numeric columns formulas may use
+	Fields map[string]string
+	// Relations are the child collections rollups may aggregate
+	Relations map[string]Relation
+	// GroupBy are the columns summaries may be grouped by
+	GroupBy map[string]string
+}
+
+var leadRelation = Relation{
+	Table:      "leads",
+	ForeignKey: "contact_id",
+	Fields: map[string]string{
+		"expected_revenue":  "expected_revenue",
+		"probability":       "probability",
+		"recurring_revenue": "recurring_revenue",
+	},
+	Filters: map[string]string{
+		"status":     "status",
+		"won_status": "won_status",
+		"lead_type":  "lead_type",
+		"priority":   "priority",
+	},
+}
+
+// Schemas lists the entities that support computed fields
+var Schemas = map[string]Schema{
+	"lead": {
+		Entity: "lead",
+		Table:  "leads",
+		Fields: map[string]string{
+			"expected_revenue":  "expected_revenue",
+			"probability":       "probability",
+			"recurring_revenue": "recurring_revenue",
+		},
+		GroupBy: map[string]string{
+			"stage_id":    "stage_id",
+			"team_id":     "team_id",
+			"assigned_to": "assigned_to",
+			"source_id":   "source_id",
+			"status":      "status",
+			"lead_type":   "lead_type",
+		},
+	},
+	"contact": {
+		Entity: "contact",
+		Table:  "contacts",
+		Fields: map[string]string{},
+		Relations: map[string]Relation{
+			"leads": leadRelation,
+			"sales_orders": {
+				Table:      "sales_orders",
+				ForeignKey: "partner_id",
+				Fields: map[string]string{
+					"amount_untaxed": "amount_untaxed",
+					"amount_total":   "amount_total",
+				},
+				Filters: map[string]string{
+					"state":          "state",
+					"invoice_status": "invoice_status",
+				},
+			},
+			"invoices": {
+				Table:      "invoices",
+				ForeignKey: "partner_id",
+				Fields: map[string]string{
+					"amount_total":    "amount_total",
+					"amount_residual": "amount_residual",
+				},
+				Filters: map[string]string{
+					"state":         "state",
+					"payment_state": "payment_state",
+				},
+			},
+		},
+		GroupBy: map[string]string{
+			"is_company": "is_company",
+			"country_id": "country_id",
+			"state_id":   "state_id",
+		},
+	},
+}
@@ -0,0 +1,65 @@
+package computed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Store loads the computed fields an organization has defined for an entity
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a computed field store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Fields loads and compiles the organization's computed fields for an entity, ordered by name
+func (s *Store) Fields(ctx context.Context, orgID uuid.UUID, entity string) ([]Field, error) {
+	query := `
+		SELECT name, kind, formula, rollup
+		FROM computed_field_definitions
+		WHERE organization_id = $1 AND entity = $2
+		ORDER BY name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load computed fields: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []Field
+	for rows.Next() {
+		def := Definition{Entity: entity}
+		var formula *string
+		var rollupJSON []byte
+		if err := rows.Scan(&def.Name, &def.Kind, &formula, &rollupJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan computed field: %w", err)
+		}
+		if formula != nil {
+			def.Formula = *formula
+		}
+		if rollupJSON != nil {
+			def.Rollup = &Rollup{}
+			if err := json.Unmarshal(rollupJSON, def.Rollup); err != nil {
+				return nil, fmt.Errorf("invalid rollup for computed field %s: %w", def.Name, err)
+			}
+		}
+
+		field, err := Compile(def)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %s: %w", def.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load computed fields: %w", err)
+	}
+	return fields, nil
+}
@@ -0,0 +1,282 @@
+// Package customfields validates the values of organization-defined fields
+// stored in an entity's custom_fields JSON column and compiles filters on
+// them to JSON containment.
+package customfields
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the data type of a custom field
+type Type string
+
+const (
+	TypeText        Type = "text"
+	TypeNumber      Type = "number"
+	TypeBoolean     Type = "boolean"
+	TypeDate        Type = "date"
+	TypeDateTime    Type = "datetime"
+	TypeSelect      Type = "select"
+	TypeMultiSelect Type = "multi_select"
+)
+
+// IsValid reports whether the field type is supported
+func (t Type) IsValid() bool {
+	switch t {
+	case TypeText, TypeNumber, TypeBoolean, TypeDate, TypeDateTime, TypeSelect, TypeMultiSelect:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrInvalidDefinition is returned for field definitions that fail validation
+	ErrInvalidDefinition = errors.New("invalid custom field definition")
+	// ErrInvalidValue is returned for custom field values that do not match their definition
+	ErrInvalidValue = errors.New("invalid custom field value")
+	// ErrUnknownField is returned when a filter names a custom field that is not defined
+	ErrUnknownField = errors.New("unknown custom field")
+)
+
+const (
+	// MaxFieldsPerEntity caps the custom fields an organization defines per entity
+	MaxFieldsPerEntity = 100
+	// MaxOptions caps the options of a select field
+	MaxOptions = 200
+	// MaxTextLength bounds text field values
+	MaxTextLength = 10000
+)
+
+// Entities maps the entities that support custom fields to the table whose
+// custom_fields column holds the values
+var Entities = map[string]string{
+	"lead":    "leads",
+	"contact": "contacts",
+}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Definition describes a custom field of an entity
+type Definition struct {
+	Entity   string `json:"entity"`
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     Type   `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	// Options lists the allowed values of select and multi_select fields
+	Options []string `json:"options,omitempty"`
+}
+
+// Validate checks a definition on its own
+func (d Definition) Validate() error {
+	if _, ok := Entities[d.Entity]; !ok {
+		return fmt.Errorf("%w: entity %q does not support custom fields", ErrInvalidDefinition, d.Entity)
+	}
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("%w: invalid name %q: use 1-50 lowercase letters, digits or underscores, starting with a letter", ErrInvalidDefinition, d.Name)
+	}
+	if strings.TrimSpace(d.Label) == "" {
+		return fmt.Errorf("%w: label is required", ErrInvalidDefinition)
+	}
+	if !d.Type.IsValid() {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidDefinition, d.Type)
+	}
+
+	if d.Type == TypeSelect || d.Type == TypeMultiSelect {
+		if len(d.Options) == 0 {
+			return fmt.Errorf("%w: %s fields need options", ErrInvalidDefinition, d.Type)
+		}
+		if len(d.Options) > MaxOptions {
+			return fmt.Errorf("%w: at most %d options are allowed", ErrInvalidDefinition, MaxOptions)
+		}
+		seen := make(map[string]bool, len(d.Options))
+		for _, o := range d.Options {
+			if o == "" || seen[o] {
+				return fmt.Errorf("%w: field %q has an empty or duplicate option", ErrInvalidDefinition, d.Name)
+			}
+			seen[o] = true
+		}
+	} else if len(d.Options) > 0 {
+		return fmt.Errorf("%w: only select and multi_select fields take options", ErrInvalidDefinition)
+	}
+	return nil
+}
+
+// Lookup returns the definition with the given name
+func Lookup(defs []Definition, name string) (Definition, bool) {
+	for _, d := range defs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Values checks decoded JSON custom field values against the definitions
+// and returns them normalized. Unknown fields are rejected, null values are
+// dropped and required fields must be set. Without definitions the entity
+// is schemaless and the values are returned unchanged.
+func Values(defs []Definition, raw interface{}) (interface{}, error) {
+	if len(defs) == 0 {
+		return raw, nil
+	}
+
+	var input map[string]interface{}
+	if raw != nil {
+		var ok bool
+		if input, ok = raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%w: custom_fields must be an object", ErrInvalidValue)
+		}
+	}
+
+	values := make(map[string]interface{}, len(input))
+	for name, value := range input {
+		if value == nil {
+			continue
+		}
+		def, ok := Lookup(defs, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidValue, name)
+		}
+		coerced, err := coerce(def, value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = coerced
+	}
+
+	for _, d := range defs {
+		if _, ok := values[d.Name]; d.Required && !ok {
+			return nil, fmt.Errorf("%w: field %q is required", ErrInvalidValue, d.Name)
+		}
+	}
+	return values, nil
+}
+
+// Contains turns equality filters, raw query values keyed by field name,
+// into the JSON document a matching record's custom fields contain. A
+// multi_select filter matches records that have the option among theirs.
+func Contains(defs []Definition, filters map[string]string) (map[string]interface{}, error) {
+	doc := make(map[string]interface{}, len(filters))
+	for name, raw := range filters {
+		def, ok := Lookup(defs, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+
+		var value interface{} = raw
+		switch def.Type {
+		case TypeNumber:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: filter %q must be a number", ErrInvalidValue, name)
+			}
+			value = n
+		case TypeBoolean:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: filter %q must be true or false", ErrInvalidValue, name)
+			}
+			value = b
+		case TypeMultiSelect:
+			value = []interface{}{raw}
+		}
+
+		coerced, err := coerce(def, value)
+		if err != nil {
+			return nil, err
+		}
+		doc[name] = coerced
+	}
+	return doc, nil
+}
+
+// coerce checks a decoded JSON value against the field type and returns it
+// in the normalized form it is stored and compared in
+func coerce(def Definition, raw interface{}) (interface{}, error) {
+	invalid := func(expected string) error {
+		return fmt.Errorf("%w: field %q must be %s", ErrInvalidValue, def.Name, expected)
+	}
+
+	switch def.Type {
+	case TypeNumber:
+		if n, ok := raw.(float64); ok {
+			return n, nil
+		}
+		return nil, invalid("a number")
+
+	case TypeBoolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+		return nil, invalid("a boolean")
+
+	case TypeMultiSelect:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, invalid("a list of " + strings.Join(def.Options, ", "))
+		}
+		selected := make([]string, 0, len(items))
+		seen := make(map[string]bool, len(items))
+		for _, item := range items {
+			str, ok := item.(string)
+			if !ok || !hasOption(def, str) {
+				return nil, invalid("a list of " + strings.Join(def.Options, ", "))
+			}
+			if !seen[str] {
+				seen[str] = true
+				selected = append(selected, str)
+			}
+		}
+		return selected, nil
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return nil, invalid("a string")
+	}
+
+	switch def.Type {
+	case TypeText:
+		if len([]rune(str)) > MaxTextLength {
+			return nil, fmt.Errorf("%w: field %q must be at most %d characters", ErrInvalidValue, def.Name, MaxTextLength)
+		}
+		return str, nil
+
+	case TypeDate:
+		d, err := time.Parse(time.DateOnly, str)
+		if err != nil {
+			return nil, invalid("a date (YYYY-MM-DD)")
+		}
+		return d.Format(time.DateOnly), nil
+
+	case TypeDateTime:
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil, invalid("an RFC 3339 timestamp")
+		}
+		// Stored in UTC at second precision so equal instants contain each other
+		return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+
+	case TypeSelect:
+		if hasOption(def, str) {
+			return str, nil
+		}
+		return nil, invalid("one of " + strings.Join(def.Options, ", "))
+	}
+	return nil, fmt.Errorf("%w: field %q has unknown type %q", ErrInvalidValue, def.Name, def.Type)
+}
+
+func hasOption(def Definition, value string) bool {
+	for _, o := range def.Options {
+		if o == value {
+			return true
+		}
+	}
+	return false
+}
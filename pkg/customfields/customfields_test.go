@@ -0,0 +1,90 @@
+package customfields
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var leadFields = []Definition{
+	{Entity: "lead", Name: "industry", Label: "Industry", Type: TypeSelect, Required: true, Options: []string{"retail", "finance"}},
+	{Entity: "lead", Name: "seats", Label: "Seats", Type: TypeNumber},
+	{Entity: "lead", Name: "renewal", Label: "Renewal", Type: TypeDateTime},
+	{Entity: "lead", Name: "channels", Label: "Channels", Type: TypeMultiSelect, Options: []string{"email", "phone", "chat"}},
+}
+
+func TestDefinitionValidate(t *testing.T) {
+	for _, def := range leadFields {
+		assert.NoError(t, def.Validate(), def.Name)
+	}
+
+	cases := map[string]Definition{
+		"unknown entity":      {Entity: "invoice", Name: "x", Label: "X", Type: TypeText},
+		"bad name":            {Entity: "lead", Name: "Bad Name", Label: "X", Type: TypeText},
+		"missing label":       {Entity: "lead", Name: "x", Type: TypeText},
+		"unknown type":        {Entity: "lead", Name: "x", Label: "X", Type: "money"},
+		"select no options":   {Entity: "lead", Name: "x", Label: "X", Type: TypeSelect},
+		"duplicate option":    {Entity: "lead", Name: "x", Label: "X", Type: TypeMultiSelect, Options: []string{"a", "a"}},
+		"options on a number": {Entity: "lead", Name: "x", Label: "X", Type: TypeNumber, Options: []string{"1"}},
+	}
+	for name, def := range cases {
+		assert.ErrorIs(t, def.Validate(), ErrInvalidDefinition, name)
+	}
+}
+
+func TestValues(t *testing.T) {
+	// Null values are dropped, even of undefined fields, and repeated
+	// options collapse
+	values, err := Values(leadFields, map[string]interface{}{
+		"industry": "retail",
+		"seats":    float64(25),
+		"renewal":  "2025-06-01T10:00:00+02:00",
+		"channels": []interface{}{"phone", "email", "phone"},
+		"seats2":   nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"industry": "retail",
+		"seats":    float64(25),
+		"renewal":  "2025-06-01T08:00:00Z",
+		"channels": []string{"phone", "email"},
+	}, values)
+
+	cases := map[string]interface{}{
+		"required missing": map[string]interface{}{"seats": float64(1)},
+		"required null":    map[string]interface{}{"industry": nil},
+		"not an option":    map[string]interface{}{"industry": "energy"},
+		"wrong type":       map[string]interface{}{"industry": "retail", "seats": "25"},
+		"not a list":       map[string]interface{}{"industry": "retail", "channels": "email"},
+		"undefined field":  map[string]interface{}{"industry": "retail", "budget": float64(1)},
+		"not an object":    []interface{}{"retail"},
+	}
+	for name, raw := range cases {
+		_, err := Values(leadFields, raw)
+		assert.ErrorIs(t, err, ErrInvalidValue, name)
+	}
+
+	// Without definitions values are stored as given
+	raw := map[string]interface{}{"anything": []interface{}{1.0}}
+	values, err = Values(nil, raw)
+	require.NoError(t, err)
+	assert.Equal(t, raw, values)
+}
+
+func TestContains(t *testing.T) {
+	doc, err := Contains(leadFields, map[string]string{"industry": "finance", "seats": "10", "channels": "chat"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"industry": "finance",
+		"seats":    float64(10),
+		"channels": []string{"chat"},
+	}, doc)
+
+	_, err = Contains(leadFields, map[string]string{"budget": "1"})
+	assert.ErrorIs(t, err, ErrUnknownField)
+	_, err = Contains(leadFields, map[string]string{"seats": "many"})
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = Contains(leadFields, map[string]string{"channels": "fax"})
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
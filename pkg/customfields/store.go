@@ -0,0 +1,49 @@
+package customfields
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Store loads the custom fields an organization has defined for an entity
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a custom field store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Definitions loads the organization's custom fields for an entity, ordered by position and name
+func (s *Store) Definitions(ctx context.Context, orgID uuid.UUID, entity string) ([]Definition, error) {
+	query := `
+		SELECT name, label, type, required, options
+		FROM custom_field_definitions
+		WHERE organization_id = $1 AND entity = $2
+		ORDER BY position, name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []Definition
+	for rows.Next() {
+		def := Definition{Entity: entity}
+		if err := rows.Scan(&def.Name, &def.Label, &def.Type, &def.Required, pq.Array(&def.Options)); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		defs = append(defs, def)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	return defs, nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect renders the SQL that differs between the databases repositories
+// can run against. Repositories write queries in PostgreSQL syntax with $n
+// placeholders, take the differing fragments from their dialect and pass
+// the finished query through Rebind.
+type Dialect interface {
+	// Name is the dialect's name as accepted by ParseDialect
+	Name() string
+	// Rebind rewrites a query written with $n placeholders for the
+	// dialect, returning the arguments in the order the query binds them
+	Rebind(query string, args []interface{}) (string, []interface{})
+	// ILike matches column case-insensitively against the LIKE pattern
+	// bound to $n, as built by LikePattern
+	ILike(column string, n int) string
+	// Upsert is the clause following INSERT ... VALUES that updates
+	// updateColumns when a row with the same conflictColumns exists, or
+	// leaves that row alone when updateColumns is empty
+	Upsert(conflictColumns, updateColumns []string) string
+	// JSONText extracts key from a JSON column as text
+	JSONText(column, key string) string
+	// JSONContains reports whether a JSON column contains the JSON
+	// document bound to $n
+	JSONContains(column string, n int) string
+	// TextRank ranks a text search vector against a text search query
+	TextRank(vector, query string) string
+	// TextHeadline returns text with the terms of query highlighted, or
+	// text unchanged where the database cannot highlight
+	TextHeadline(text, query, options string) string
+	// SupportsAdvisoryLocks reports whether pg_try_advisory_lock is
+	// available for leader election
+	SupportsAdvisoryLocks() bool
+}
+
+// The supported dialects
+var (
+	Postgres    Dialect = postgresDialect{}
+	CockroachDB Dialect = cockroachDialect{}
+	MySQL       Dialect = mysqlDialect{}
+)
+
+// ParseDialect returns the dialect named by a DB_DIALECT setting,
+// defaulting to PostgreSQL
+func ParseDialect(name string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "postgres", "postgresql":
+		return Postgres, nil
+	case "cockroachdb", "cockroach":
+		return CockroachDB, nil
+	case "mysql":
+		return MySQL, nil
+	default:
+		return nil, fmt.Errorf("invalid database dialect %q: must be one of postgres, cockroachdb, mysql", name)
+	}
+}
+
+// DialectOrDefault returns d, or PostgreSQL when d is nil
+func DialectOrDefault(d Dialect) Dialect {
+	if d == nil {
+		return Postgres
+	}
+	return d
+}
+
+// postgresDialect renders PostgreSQL, the dialect queries are written in
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	return query, args
+}
+
+func (postgresDialect) ILike(column string, n int) string {
+	return ILike(column, n)
+}
+
+func (postgresDialect) Upsert(conflictColumns, updateColumns []string) string {
+	clause := "ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ")"
+	if len(updateColumns) == 0 {
+		return clause + " DO NOTHING"
+	}
+	sets := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		sets[i] = column + " = EXCLUDED." + column
+	}
+	return clause + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+func (postgresDialect) JSONText(column, key string) string {
+	return column + "->>" + quoteLiteral(key)
+}
+
+func (postgresDialect) JSONContains(column string, n int) string {
+	return fmt.Sprintf("%s @> $%d", column, n)
+}
+
+func (postgresDialect) TextRank(vector, query string) string {
+	return "ts_rank_cd(" + vector + ", " + query + ")"
+}
+
+func (postgresDialect) TextHeadline(text, query, options string) string {
+	return "ts_headline('simple', " + text + ", " + query + ", " + quoteLiteral(options) + ")"
+}
+
+func (postgresDialect) SupportsAdvisoryLocks() bool { return true }
+
+// cockroachDialect renders CockroachDB, which speaks the PostgreSQL wire
+// protocol and syntax but lacks cover-density ranking, headlines and
+// advisory locks
+type cockroachDialect struct {
+	postgresDialect
+}
+
+func (cockroachDialect) Name() string { return "cockroachdb" }
+
+func (cockroachDialect) TextRank(vector, query string) string {
+	return "ts_rank(" + vector + ", " + query + ")"
+}
+
+func (cockroachDialect) TextHeadline(text, query, options string) string {
+	return text
+}
+
+func (cockroachDialect) SupportsAdvisoryLocks() bool { return false }
+
+// mysqlDialect renders MySQL 8, which binds positional ? placeholders and
+// has no text search vectors
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+// Rebind replaces each $n outside string literals with ?, repeating the
+// argument of a placeholder used more than once
+func (mysqlDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	var out strings.Builder
+	var bound []interface{}
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			inString = !inString
+		}
+		if c != '$' || inString {
+			out.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(query[i+1 : j])
+		if err != nil || n < 1 || n > len(args) {
+			out.WriteByte(c)
+			continue
+		}
+		out.WriteByte('?')
+		bound = append(bound, args[n-1])
+		i = j - 1
+	}
+	return out.String(), bound
+}
+
+func (mysqlDialect) ILike(column string, n int) string {
+	return fmt.Sprintf(`LOWER(%s) LIKE LOWER($%d) ESCAPE '\\'`, column, n)
+}
+
+func (mysqlDialect) Upsert(conflictColumns, updateColumns []string) string {
+	if len(updateColumns) == 0 {
+		// A no-op assignment keeps the existing row, like DO NOTHING
+		return "ON DUPLICATE KEY UPDATE " + conflictColumns[0] + " = " + conflictColumns[0]
+	}
+	sets := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		sets[i] = column + " = VALUES(" + column + ")"
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) JSONText(column, key string) string {
+	return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", " + quoteLiteral(`$."`+key+`"`) + "))"
+}
+
+func (mysqlDialect) JSONContains(column string, n int) string {
+	return fmt.Sprintf("JSON_CONTAINS(%s, $%d)", column, n)
+}
+
+func (mysqlDialect) TextRank(vector, query string) string {
+	return "0"
+}
+
+func (mysqlDialect) TextHeadline(text, query, options string) string {
+	return text
+}
+
+func (mysqlDialect) SupportsAdvisoryLocks() bool { return false }
+
+// quoteLiteral quotes s as an SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
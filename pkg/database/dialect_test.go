@@ -0,0 +1,103 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test dialect names round trip through ParseDialect
+func TestParseDialect(t *testing.T) {
+	for _, name := range []string{"", "postgres", "PostgreSQL", " cockroachdb ", "mysql"} {
+		dialect, err := ParseDialect(name)
+		require.NoError(t, err, name)
+
+		parsed, err := ParseDialect(dialect.Name())
+		require.NoError(t, err)
+		assert.Equal(t, dialect, parsed)
+	}
+
+	_, err := ParseDialect("sqlite")
+	assert.Error(t, err)
+
+	assert.Equal(t, Postgres, DialectOrDefault(nil))
+	assert.Equal(t, MySQL, DialectOrDefault(MySQL))
+}
+
+// Test every fragment under every dialect
+func TestDialectMatrix(t *testing.T) {
+	tests := []struct {
+		dialect      Dialect
+		ilike        string
+		upsert       string
+		doNothing    string
+		jsonText     string
+		jsonContains string
+		rank         string
+		headline     string
+		advisory     bool
+	}{
+		{
+			dialect:      Postgres,
+			ilike:        `name ILIKE $2 ESCAPE '\'`,
+			upsert:       "ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, email = EXCLUDED.email",
+			doNothing:    "ON CONFLICT (id) DO NOTHING",
+			jsonText:     "metadata->>'source'",
+			jsonContains: "metadata @> $3",
+			rank:         "ts_rank_cd(v, q)",
+			headline:     "ts_headline('simple', t, q, 'MaxWords=10')",
+			advisory:     true,
+		},
+		{
+			dialect:      CockroachDB,
+			ilike:        `name ILIKE $2 ESCAPE '\'`,
+			upsert:       "ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, email = EXCLUDED.email",
+			doNothing:    "ON CONFLICT (id) DO NOTHING",
+			jsonText:     "metadata->>'source'",
+			jsonContains: "metadata @> $3",
+			rank:         "ts_rank(v, q)",
+			headline:     "t",
+		},
+		{
+			dialect:      MySQL,
+			ilike:        `LOWER(name) LIKE LOWER($2) ESCAPE '\\'`,
+			upsert:       "ON DUPLICATE KEY UPDATE name = VALUES(name), email = VALUES(email)",
+			doNothing:    "ON DUPLICATE KEY UPDATE id = id",
+			jsonText:     `JSON_UNQUOTE(JSON_EXTRACT(metadata, '$."source"'))`,
+			jsonContains: "JSON_CONTAINS(metadata, $3)",
+			rank:         "0",
+			headline:     "t",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dialect.Name(), func(t *testing.T) {
+			d := test.dialect
+			assert.Equal(t, test.ilike, d.ILike("name", 2))
+			assert.Equal(t, test.upsert, d.Upsert([]string{"id"}, []string{"name", "email"}))
+			assert.Equal(t, test.doNothing, d.Upsert([]string{"id"}, nil))
+			assert.Equal(t, test.jsonText, d.JSONText("metadata", "source"))
+			assert.Equal(t, test.jsonContains, d.JSONContains("metadata", 3))
+			assert.Equal(t, test.rank, d.TextRank("v", "q"))
+			assert.Equal(t, test.headline, d.TextHeadline("t", "q", "MaxWords=10"))
+			assert.Equal(t, test.advisory, d.SupportsAdvisoryLocks())
+		})
+	}
+}
+
+// Test placeholder rebinding
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM leads WHERE organization_id = $1 AND (name = $2 OR email = $2) AND note <> '$1' LIMIT $3"
+	args := []interface{}{"org", "acme", 10}
+
+	for _, d := range []Dialect{Postgres, CockroachDB} {
+		rebound, boundArgs := d.Rebind(query, args)
+		assert.Equal(t, query, rebound, d.Name())
+		assert.Equal(t, args, boundArgs, d.Name())
+	}
+
+	rebound, boundArgs := MySQL.Rebind(query, args)
+	assert.Equal(t, "SELECT * FROM leads WHERE organization_id = ? AND (name = ? OR email = ?) AND note <> '$1' LIMIT ?", rebound)
+	assert.Equal(t, []interface{}{"org", "acme", "acme", 10}, boundArgs)
+}
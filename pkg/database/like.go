@@ -0,0 +1,56 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchMode controls how a text filter value is matched against a column
+type MatchMode string
+
+const (
+	MatchModeExact    MatchMode = "exact"
+	MatchModePrefix   MatchMode = "prefix"
+	MatchModeContains MatchMode = "contains"
+)
+
+// LikeEscape is the ESCAPE clause that must follow every ILIKE built with LikePattern
+const LikeEscape = `ESCAPE '\'`
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ParseMatchMode parses a match_mode query parameter, defaulting to contains
+func ParseMatchMode(s string) (MatchMode, error) {
+	switch mode := MatchMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return MatchModeContains, nil
+	case MatchModeExact, MatchModePrefix, MatchModeContains:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid match_mode %q: must be one of exact, prefix, contains", s)
+	}
+}
+
+// EscapeLike escapes LIKE wildcards so user input is matched literally
+func EscapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
+
+// LikePattern builds an escaped ILIKE pattern for value according to mode.
+// An empty mode behaves like MatchModeContains.
+func LikePattern(value string, mode MatchMode) string {
+	escaped := EscapeLike(value)
+	switch mode {
+	case MatchModeExact:
+		return escaped
+	case MatchModePrefix:
+		return escaped + "%"
+	default:
+		return "%" + escaped + "%"
+	}
+}
+
+// ILike returns an escaped "column ILIKE $n" condition
+func ILike(column string, argIndex int) string {
+	return fmt.Sprintf("%s ILIKE $%d %s", column, argIndex, LikeEscape)
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test wildcard escaping and match modes
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		value    string
+		mode     MatchMode
+		expected string
+	}{
+		{"acme", MatchModeContains, "%acme%"},
+		{"acme", "", "%acme%"},
+		{"acme", MatchModePrefix, "acme%"},
+		{"acme", MatchModeExact, "acme"},
+		{"50%", MatchModeContains, `%50\%%`},
+		{"a_b", MatchModeExact, `a\_b`},
+		{`c:\tmp`, MatchModePrefix, `c:\\tmp%`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, LikePattern(test.value, test.mode), "Failed for value: %s", test.value)
+	}
+}
+
+// Test match_mode parsing
+func TestParseMatchMode(t *testing.T) {
+	mode, err := ParseMatchMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, MatchModeContains, mode)
+
+	mode, err = ParseMatchMode("Prefix")
+	assert.NoError(t, err)
+	assert.Equal(t, MatchModePrefix, mode)
+
+	_, err = ParseMatchMode("regex")
+	assert.Error(t, err)
+
+	assert.Equal(t, `name ILIKE $3 ESCAPE '\'`, ILike("name", 3))
+}
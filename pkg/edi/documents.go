@@ -0,0 +1,453 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DocumentType is a business document independent of its standard
+type DocumentType string
+
+const (
+	DocumentPurchaseOrder DocumentType = "purchase_order"
+	DocumentShipNotice    DocumentType = "ship_notice"
+	DocumentInvoice       DocumentType = "invoice"
+)
+
+var messageTypes = map[Standard]map[DocumentType]string{
+	StandardX12: {
+		DocumentPurchaseOrder: "850",
+		DocumentShipNotice:    "856",
+		DocumentInvoice:       "810",
+	},
+	StandardEDIFACT: {
+		DocumentPurchaseOrder: "ORDERS",
+		DocumentShipNotice:    "DESADV",
+		DocumentInvoice:       "INVOIC",
+	},
+}
+
+// MessageType returns the transaction set or message type of a document in
+// a standard, e.g. 850 or ORDERS for a purchase order
+func MessageType(standard Standard, document DocumentType) string {
+	return messageTypes[standard][document]
+}
+
+// DocumentTypeOf returns the document a message type carries, or "" when
+// the message type is not supported
+func DocumentTypeOf(standard Standard, messageType string) DocumentType {
+	for document, t := range messageTypes[standard] {
+		if t == messageType {
+			return document
+		}
+	}
+	return ""
+}
+
+// Party is a buyer, ship-to or bill-to party. Qualifier is the party role
+// code: ST, BT or BY.
+type Party struct {
+	Qualifier  string `json:"qualifier"`
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// LineItem is an ordered, shipped or invoiced product. ProductCode is the
+// buyer's part number or, without one, the vendor's.
+type LineItem struct {
+	LineNumber  string  `json:"line_number"`
+	ProductCode string  `json:"product_code"`
+	VendorCode  string  `json:"vendor_code,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	UOM         string  `json:"uom,omitempty"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// PurchaseOrder is an inbound 850 or ORDERS document
+type PurchaseOrder struct {
+	Number        string     `json:"number"`
+	Date          time.Time  `json:"date"`
+	DeliveryDate  *time.Time `json:"delivery_date,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	Parties       []Party    `json:"parties"`
+	Lines         []LineItem `json:"lines"`
+	ControlNumber string     `json:"control_number"`
+}
+
+// Party returns the party with a role qualifier, or nil
+func (po PurchaseOrder) Party(qualifier string) *Party {
+	for i := range po.Parties {
+		if po.Parties[i].Qualifier == qualifier {
+			return &po.Parties[i]
+		}
+	}
+	return nil
+}
+
+// ShipTo returns the ship-to party (X12 ST) or delivery party (EDIFACT DP),
+// or nil
+func (po PurchaseOrder) ShipTo() *Party {
+	if p := po.Party("ST"); p != nil {
+		return p
+	}
+	return po.Party("DP")
+}
+
+// ShipNotice is an outbound 856 or DESADV document
+type ShipNotice struct {
+	ShipmentID     string     `json:"shipment_id"`
+	PurchaseOrder  string     `json:"purchase_order"`
+	ShipDate       time.Time  `json:"ship_date"`
+	CarrierCode    string     `json:"carrier_code,omitempty"`
+	CarrierName    string     `json:"carrier_name,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	ShipTo         Party      `json:"ship_to"`
+	Lines          []LineItem `json:"lines"`
+}
+
+// Invoice is an outbound 810 or INVOIC document
+type Invoice struct {
+	Number        string     `json:"number"`
+	Date          time.Time  `json:"date"`
+	PurchaseOrder string     `json:"purchase_order,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	BillTo        Party      `json:"bill_to"`
+	Lines         []LineItem `json:"lines"`
+	TotalAmount   float64    `json:"total_amount"`
+}
+
+// DecodePurchaseOrder maps an 850 or ORDERS message to a purchase order
+func DecodePurchaseOrder(standard Standard, m Message) (*PurchaseOrder, error) {
+	if DocumentTypeOf(standard, m.Type) != DocumentPurchaseOrder {
+		return nil, fmt.Errorf("%w: %s is not a purchase order", ErrMalformed, m.Type)
+	}
+
+	var po *PurchaseOrder
+	var err error
+	if standard == StandardX12 {
+		po, err = decodeX12PurchaseOrder(m)
+	} else {
+		po, err = decodeEDIFACTPurchaseOrder(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if po.Number == "" {
+		return nil, fmt.Errorf("%w: purchase order %s has no number", ErrMalformed, m.ControlNumber)
+	}
+	if len(po.Lines) == 0 {
+		return nil, fmt.Errorf("%w: purchase order %s has no lines", ErrMalformed, po.Number)
+	}
+	po.ControlNumber = m.ControlNumber
+	return po, nil
+}
+
+func decodeX12PurchaseOrder(m Message) (*PurchaseOrder, error) {
+	po := &PurchaseOrder{}
+	var party *Party
+	var line *LineItem
+	for _, s := range m.Segments {
+		switch s.Tag {
+		case "BEG":
+			po.Number = s.Element(3)
+			po.Date, _ = time.Parse("20060102", s.Element(5))
+		case "CUR":
+			po.Currency = s.Element(2)
+		case "DTM":
+			if s.Element(1) == "002" {
+				if t, err := time.Parse("20060102", s.Element(2)); err == nil {
+					po.DeliveryDate = &t
+				}
+			}
+		case "N1":
+			po.Parties = append(po.Parties, Party{Qualifier: s.Element(1), Name: s.Element(2), ID: s.Element(4)})
+			party = &po.Parties[len(po.Parties)-1]
+		case "N3":
+			if party != nil {
+				party.Street = s.Element(1)
+			}
+		case "N4":
+			if party != nil {
+				party.City, party.State, party.PostalCode, party.Country = s.Element(1), s.Element(2), s.Element(3), s.Element(4)
+			}
+		case "PO1":
+			item, err := x12LineItem(s, 2)
+			if err != nil {
+				return nil, err
+			}
+			po.Lines = append(po.Lines, item)
+			line = &po.Lines[len(po.Lines)-1]
+		case "PID":
+			if line != nil && line.Description == "" {
+				line.Description = s.Element(5)
+			}
+		}
+	}
+	return po, nil
+}
+
+// x12LineItem reads a PO1 or IT1 segment: line number, quantity, unit,
+// price, basis and then qualifier and product ID pairs
+func x12LineItem(s Segment, quantityElement int) (LineItem, error) {
+	item := LineItem{LineNumber: s.Element(1), UOM: s.Element(quantityElement + 1)}
+	var err error
+	if item.Quantity, err = strconv.ParseFloat(s.Element(quantityElement), 64); err != nil {
+		return item, fmt.Errorf("%w: line %s has an invalid quantity", ErrMalformed, item.LineNumber)
+	}
+	if v := s.Element(quantityElement + 2); v != "" {
+		if item.UnitPrice, err = strconv.ParseFloat(v, 64); err != nil {
+			return item, fmt.Errorf("%w: line %s has an invalid price", ErrMalformed, item.LineNumber)
+		}
+	}
+	for i := quantityElement + 4; i+1 <= len(s.Elements); i += 2 {
+		switch s.Element(i) {
+		case "BP", "IN":
+			item.ProductCode = s.Element(i + 1)
+		case "VP", "VN", "SK":
+			item.VendorCode = s.Element(i + 1)
+		}
+	}
+	if item.ProductCode == "" {
+		item.ProductCode = item.VendorCode
+	}
+	return item, nil
+}
+
+func decodeEDIFACTPurchaseOrder(m Message) (*PurchaseOrder, error) {
+	po := &PurchaseOrder{}
+	var line *LineItem
+	for _, s := range m.Segments {
+		switch s.Tag {
+		case "BGM":
+			po.Number = s.Element(2)
+		case "DTM":
+			t, err := edifactDate(s)
+			if err != nil {
+				continue
+			}
+			switch s.Component(1, 1) {
+			case "137":
+				po.Date = t
+			case "2":
+				po.DeliveryDate = &t
+			}
+		case "CUX":
+			po.Currency = s.Component(1, 2)
+		case "NAD":
+			po.Parties = append(po.Parties, Party{
+				Qualifier:  s.Element(1),
+				ID:         s.Component(2, 1),
+				Name:       s.Component(4, 1),
+				Street:     s.Component(5, 1),
+				City:       s.Element(6),
+				State:      s.Element(7),
+				PostalCode: s.Element(8),
+				Country:    s.Element(9),
+			})
+			if name := s.Component(3, 1); po.Parties[len(po.Parties)-1].Name == "" {
+				po.Parties[len(po.Parties)-1].Name = name
+			}
+		case "LIN":
+			po.Lines = append(po.Lines, LineItem{LineNumber: s.Element(1), ProductCode: s.Component(3, 1)})
+			line = &po.Lines[len(po.Lines)-1]
+		case "PIA":
+			if line != nil && (s.Component(2, 2) == "SA" || s.Component(2, 2) == "VN") {
+				line.VendorCode = s.Component(2, 1)
+				if line.ProductCode == "" {
+					line.ProductCode = line.VendorCode
+				}
+			}
+		case "IMD":
+			if line != nil && line.Description == "" {
+				line.Description = s.Component(3, 4)
+			}
+		case "QTY":
+			if line != nil && s.Component(1, 1) == "21" {
+				q, err := strconv.ParseFloat(s.Component(1, 2), 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %s has an invalid quantity", ErrMalformed, line.LineNumber)
+				}
+				line.Quantity = q
+				line.UOM = s.Component(1, 3)
+			}
+		case "PRI":
+			if line != nil {
+				p, err := strconv.ParseFloat(s.Component(1, 2), 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %s has an invalid price", ErrMalformed, line.LineNumber)
+				}
+				line.UnitPrice = p
+			}
+		}
+	}
+	return po, nil
+}
+
+// edifactDate reads a DTM segment in format 102 (CCYYMMDD) or 203 (CCYYMMDDHHMM)
+func edifactDate(s Segment) (time.Time, error) {
+	if s.Component(1, 3) == "203" {
+		return time.Parse("200601021504", s.Component(1, 2))
+	}
+	return time.Parse("20060102", s.Component(1, 2))
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ShipNoticeMessage builds the 856 or DESADV message of a ship notice
+func ShipNoticeMessage(standard Standard, notice ShipNotice) Message {
+	m := Message{Type: MessageType(standard, DocumentShipNotice)}
+	if standard == StandardX12 {
+		m.Segments = append(m.Segments,
+			NewSegment("BSN", "00", notice.ShipmentID, notice.ShipDate.Format("20060102"), notice.ShipDate.Format("1504")),
+			NewSegment("HL", "1", "", "S"),
+			NewSegment("TD5", "", "2", notice.CarrierCode, "", notice.CarrierName),
+		)
+		if notice.TrackingNumber != "" {
+			m.Segments = append(m.Segments, NewSegment("REF", "CN", notice.TrackingNumber))
+		}
+		m.Segments = append(m.Segments, NewSegment("DTM", "011", notice.ShipDate.Format("20060102")))
+		m.Segments = append(m.Segments, x12Party(notice.ShipTo)...)
+		m.Segments = append(m.Segments,
+			NewSegment("HL", "2", "1", "O"),
+			NewSegment("PRF", notice.PurchaseOrder),
+		)
+		for i, line := range notice.Lines {
+			m.Segments = append(m.Segments,
+				NewSegment("HL", strconv.Itoa(i+3), "2", "I"),
+				NewSegment("LIN", line.LineNumber, "BP", line.ProductCode, vendorQualifier(line), line.VendorCode),
+				NewSegment("SN1", "", formatAmount(line.Quantity), uomOr(line.UOM)),
+			)
+		}
+		m.Segments = append(m.Segments, NewSegment("CTT", strconv.Itoa(len(notice.Lines)+2)))
+		return m
+	}
+
+	m.Segments = append(m.Segments,
+		NewSegment("BGM", "351", notice.ShipmentID, "9"),
+		Segment{Tag: "DTM", Elements: [][]string{{"11", notice.ShipDate.Format("20060102"), "102"}}},
+		Segment{Tag: "RFF", Elements: [][]string{{"ON", notice.PurchaseOrder}}},
+	)
+	if notice.TrackingNumber != "" {
+		m.Segments = append(m.Segments, Segment{Tag: "RFF", Elements: [][]string{{"CN", notice.TrackingNumber}}})
+	}
+	m.Segments = append(m.Segments, edifactParty(notice.ShipTo))
+	if notice.CarrierCode != "" || notice.CarrierName != "" {
+		m.Segments = append(m.Segments, Segment{Tag: "TDT", Elements: [][]string{
+			{"20"}, {""}, {""}, {""}, {notice.CarrierCode, "", "182", notice.CarrierName},
+		}})
+	}
+	m.Segments = append(m.Segments, NewSegment("CPS", "1"))
+	for _, line := range notice.Lines {
+		m.Segments = append(m.Segments,
+			Segment{Tag: "LIN", Elements: [][]string{{line.LineNumber}, {""}, {line.ProductCode, "BP"}}},
+			Segment{Tag: "QTY", Elements: [][]string{{"12", formatAmount(line.Quantity), line.UOM}}},
+		)
+	}
+	return m
+}
+
+// InvoiceMessage builds the 810 or INVOIC message of an invoice
+func InvoiceMessage(standard Standard, invoice Invoice) Message {
+	m := Message{Type: MessageType(standard, DocumentInvoice)}
+	if standard == StandardX12 {
+		m.Segments = append(m.Segments, NewSegment("BIG", invoice.Date.Format("20060102"), invoice.Number, "", invoice.PurchaseOrder))
+		if invoice.Currency != "" {
+			m.Segments = append(m.Segments, NewSegment("CUR", "SE", invoice.Currency))
+		}
+		m.Segments = append(m.Segments, x12Party(invoice.BillTo)...)
+		for _, line := range invoice.Lines {
+			m.Segments = append(m.Segments,
+				NewSegment("IT1", line.LineNumber, formatAmount(line.Quantity), uomOr(line.UOM),
+					formatAmount(line.UnitPrice), "", "BP", line.ProductCode, vendorQualifier(line), line.VendorCode),
+			)
+			if line.Description != "" {
+				m.Segments = append(m.Segments, NewSegment("PID", "F", "", "", "", line.Description))
+			}
+		}
+		// TDS carries the total in cents
+		m.Segments = append(m.Segments,
+			NewSegment("TDS", strconv.FormatInt(int64(invoice.TotalAmount*100+0.5), 10)),
+			NewSegment("CTT", strconv.Itoa(len(invoice.Lines))),
+		)
+		return m
+	}
+
+	m.Segments = append(m.Segments,
+		NewSegment("BGM", "380", invoice.Number, "9"),
+		Segment{Tag: "DTM", Elements: [][]string{{"137", invoice.Date.Format("20060102"), "102"}}},
+	)
+	if invoice.PurchaseOrder != "" {
+		m.Segments = append(m.Segments, Segment{Tag: "RFF", Elements: [][]string{{"ON", invoice.PurchaseOrder}}})
+	}
+	m.Segments = append(m.Segments, edifactParty(invoice.BillTo))
+	if invoice.Currency != "" {
+		m.Segments = append(m.Segments, Segment{Tag: "CUX", Elements: [][]string{{"2", invoice.Currency, "4"}}})
+	}
+	for _, line := range invoice.Lines {
+		m.Segments = append(m.Segments,
+			Segment{Tag: "LIN", Elements: [][]string{{line.LineNumber}, {""}, {line.ProductCode, "BP"}}},
+		)
+		if line.Description != "" {
+			m.Segments = append(m.Segments, Segment{Tag: "IMD", Elements: [][]string{{"F"}, {""}, {"", "", "", line.Description}}})
+		}
+		m.Segments = append(m.Segments,
+			Segment{Tag: "QTY", Elements: [][]string{{"47", formatAmount(line.Quantity), line.UOM}}},
+			Segment{Tag: "MOA", Elements: [][]string{{"203", formatAmount(line.Quantity * line.UnitPrice)}}},
+			Segment{Tag: "PRI", Elements: [][]string{{"AAA", formatAmount(line.UnitPrice)}}},
+		)
+	}
+	m.Segments = append(m.Segments,
+		NewSegment("UNS", "S"),
+		Segment{Tag: "MOA", Elements: [][]string{{"86", formatAmount(invoice.TotalAmount)}}},
+	)
+	return m
+}
+
+func x12Party(p Party) []Segment {
+	idQualifier := ""
+	if p.ID != "" {
+		idQualifier = "92"
+	}
+	segments := []Segment{NewSegment("N1", p.Qualifier, p.Name, idQualifier, p.ID)}
+	if p.Street != "" {
+		segments = append(segments, NewSegment("N3", p.Street))
+	}
+	if p.City != "" || p.PostalCode != "" {
+		segments = append(segments, NewSegment("N4", p.City, p.State, p.PostalCode, p.Country))
+	}
+	return segments
+}
+
+func edifactParty(p Party) Segment {
+	id := []string{""}
+	if p.ID != "" {
+		id = []string{p.ID, "", "92"}
+	}
+	return Segment{Tag: "NAD", Elements: [][]string{
+		{p.Qualifier}, id, {""}, {p.Name}, {p.Street}, {p.City}, {p.State}, {p.PostalCode}, {p.Country},
+	}}
+}
+
+func vendorQualifier(line LineItem) string {
+	if line.VendorCode == "" {
+		return ""
+	}
+	return "VP"
+}
+
+func uomOr(uom string) string {
+	if uom == "" {
+		return "EA"
+	}
+	return strings.ToUpper(uom)
+}
@@ -0,0 +1,456 @@
+package edi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard is an EDI syntax
+type Standard string
+
+const (
+	StandardX12     Standard = "x12"
+	StandardEDIFACT Standard = "edifact"
+)
+
+// IsValid reports whether the standard is supported
+func (s Standard) IsValid() bool {
+	return s == StandardX12 || s == StandardEDIFACT
+}
+
+// ErrMalformed is returned for interchanges that cannot be parsed
+var ErrMalformed = errors.New("malformed EDI interchange")
+
+// Delimiters separate the parts of an interchange. Release escapes a
+// delimiter inside a value; X12 has no release character.
+type Delimiters struct {
+	Segment   byte
+	Element   byte
+	Component byte
+	Release   byte
+}
+
+// DefaultDelimiters returns the delimiters written for a standard
+func DefaultDelimiters(standard Standard) Delimiters {
+	if standard == StandardEDIFACT {
+		return Delimiters{Segment: '\'', Element: '+', Component: ':', Release: '?'}
+	}
+	return Delimiters{Segment: '~', Element: '*', Component: '>'}
+}
+
+// Segment is one segment of an interchange. Elements holds the components of
+// each element after the tag.
+type Segment struct {
+	Tag      string
+	Elements [][]string
+}
+
+// NewSegment builds a segment of simple elements
+func NewSegment(tag string, elements ...string) Segment {
+	s := Segment{Tag: tag, Elements: make([][]string, len(elements))}
+	for i, e := range elements {
+		s.Elements[i] = []string{e}
+	}
+	return s
+}
+
+// Element returns the first component of the 1-based element i, or "" when
+// the segment is shorter
+func (s Segment) Element(i int) string {
+	return s.Component(i, 1)
+}
+
+// Component returns the 1-based component j of the 1-based element i, or ""
+// when it is absent
+func (s Segment) Component(i, j int) string {
+	if i < 1 || i > len(s.Elements) || j < 1 || j > len(s.Elements[i-1]) {
+		return ""
+	}
+	return s.Elements[i-1][j-1]
+}
+
+// Envelope identifies the sender and receiver of an interchange
+type Envelope struct {
+	Standard          Standard  `json:"standard"`
+	SenderID          string    `json:"sender_id"`
+	SenderQualifier   string    `json:"sender_qualifier,omitempty"`
+	ReceiverID        string    `json:"receiver_id"`
+	ReceiverQualifier string    `json:"receiver_qualifier,omitempty"`
+	ControlNumber     string    `json:"control_number"`
+	Date              time.Time `json:"date"`
+	Test              bool      `json:"test"`
+}
+
+// Message is one transaction set (X12) or message (EDIFACT) of an
+// interchange. Segments excludes the ST/SE or UNH/UNT segments around it.
+type Message struct {
+	Type          string
+	ControlNumber string
+	Segments      []Segment
+}
+
+// Interchange is a parsed EDI interchange
+type Interchange struct {
+	Envelope   Envelope
+	Delimiters Delimiters
+	Messages   []Message
+}
+
+// Parse reads an X12 or EDIFACT interchange, detected from its first segment
+func Parse(data []byte) (*Interchange, error) {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case bytes.HasPrefix(data, []byte("ISA")):
+		return parseX12(data)
+	case bytes.HasPrefix(data, []byte("UNA")), bytes.HasPrefix(data, []byte("UNB")):
+		return parseEDIFACT(data)
+	default:
+		return nil, fmt.Errorf("%w: expected an ISA or UNB segment", ErrMalformed)
+	}
+}
+
+// split tokenizes data into segments using the delimiters
+func split(data []byte, d Delimiters) []Segment {
+	var segments []Segment
+	var elements [][]string
+	var components []string
+	var value strings.Builder
+
+	endComponent := func() {
+		components = append(components, value.String())
+		value.Reset()
+	}
+	endElement := func() {
+		endComponent()
+		elements = append(elements, components)
+		components = nil
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case d.Release != 0 && c == d.Release && i+1 < len(data):
+			i++
+			value.WriteByte(data[i])
+		case c == d.Segment:
+			endElement()
+			if tag := strings.TrimSpace(elements[0][0]); tag != "" {
+				segments = append(segments, Segment{Tag: tag, Elements: elements[1:]})
+			}
+			elements = nil
+		case c == d.Element:
+			endElement()
+		case c == d.Component:
+			endComponent()
+		case (c == '\r' || c == '\n') && len(elements) == 0 && len(components) == 0 && value.Len() == 0:
+			// line breaks between segments
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return segments
+}
+
+func parseX12(data []byte) (*Interchange, error) {
+	// ISA is fixed width: its 106th byte ends the segment and the component
+	// separator is the last element
+	if len(data) < 106 {
+		return nil, fmt.Errorf("%w: ISA segment is too short", ErrMalformed)
+	}
+	d := Delimiters{Element: data[3], Component: data[104], Segment: data[105]}
+	segments := split(data, d)
+	if len(segments) == 0 || segments[0].Tag != "ISA" || len(segments[0].Elements) < 16 {
+		return nil, fmt.Errorf("%w: invalid ISA segment", ErrMalformed)
+	}
+	isa := segments[0]
+	isa.Elements[15] = []string{string(d.Component)}
+
+	ic := &Interchange{
+		Delimiters: d,
+		Envelope: Envelope{
+			Standard:          StandardX12,
+			SenderQualifier:   strings.TrimSpace(isa.Element(5)),
+			SenderID:          strings.TrimSpace(isa.Element(6)),
+			ReceiverQualifier: strings.TrimSpace(isa.Element(7)),
+			ReceiverID:        strings.TrimSpace(isa.Element(8)),
+			ControlNumber:     strings.TrimLeft(isa.Element(13), "0"),
+			Test:              isa.Element(15) == "T",
+		},
+	}
+	ic.Envelope.Date, _ = time.Parse("060102 1504", isa.Element(9)+" "+isa.Element(10))
+
+	var current *Message
+	for _, s := range segments[1:] {
+		switch s.Tag {
+		case "ST":
+			if current != nil {
+				return nil, fmt.Errorf("%w: transaction set %s has no SE segment", ErrMalformed, current.ControlNumber)
+			}
+			current = &Message{Type: s.Element(1), ControlNumber: s.Element(2)}
+		case "SE":
+			if current == nil {
+				return nil, fmt.Errorf("%w: SE segment outside a transaction set", ErrMalformed)
+			}
+			if n, err := strconv.Atoi(s.Element(1)); err != nil || n != len(current.Segments)+2 {
+				return nil, fmt.Errorf("%w: transaction set %s has a wrong segment count", ErrMalformed, current.ControlNumber)
+			}
+			ic.Messages = append(ic.Messages, *current)
+			current = nil
+		case "GS", "GE", "IEA":
+		default:
+			if current != nil {
+				current.Segments = append(current.Segments, s)
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%w: transaction set %s has no SE segment", ErrMalformed, current.ControlNumber)
+	}
+	return ic, nil
+}
+
+func parseEDIFACT(data []byte) (*Interchange, error) {
+	d := DefaultDelimiters(StandardEDIFACT)
+	if bytes.HasPrefix(data, []byte("UNA")) {
+		// UNA sets component, element, decimal mark, release, reserved and
+		// segment characters
+		if len(data) < 9 {
+			return nil, fmt.Errorf("%w: UNA segment is too short", ErrMalformed)
+		}
+		d = Delimiters{Component: data[3], Element: data[4], Release: data[6], Segment: data[8]}
+		if d.Release == ' ' {
+			d.Release = 0
+		}
+		data = data[9:]
+	}
+
+	segments := split(data, d)
+	if len(segments) == 0 || segments[0].Tag != "UNB" {
+		return nil, fmt.Errorf("%w: expected a UNB segment", ErrMalformed)
+	}
+	unb := segments[0]
+	ic := &Interchange{
+		Delimiters: d,
+		Envelope: Envelope{
+			Standard:          StandardEDIFACT,
+			SenderID:          unb.Component(2, 1),
+			SenderQualifier:   unb.Component(2, 2),
+			ReceiverID:        unb.Component(3, 1),
+			ReceiverQualifier: unb.Component(3, 2),
+			ControlNumber:     unb.Element(5),
+			Test:              unb.Element(11) == "1",
+		},
+	}
+	ic.Envelope.Date, _ = time.Parse("060102 1504", unb.Component(4, 1)+" "+unb.Component(4, 2))
+
+	var current *Message
+	for _, s := range segments[1:] {
+		switch s.Tag {
+		case "UNH":
+			if current != nil {
+				return nil, fmt.Errorf("%w: message %s has no UNT segment", ErrMalformed, current.ControlNumber)
+			}
+			current = &Message{Type: s.Component(2, 1), ControlNumber: s.Element(1)}
+		case "UNT":
+			if current == nil {
+				return nil, fmt.Errorf("%w: UNT segment outside a message", ErrMalformed)
+			}
+			if n, err := strconv.Atoi(s.Element(1)); err != nil || n != len(current.Segments)+2 {
+				return nil, fmt.Errorf("%w: message %s has a wrong segment count", ErrMalformed, current.ControlNumber)
+			}
+			ic.Messages = append(ic.Messages, *current)
+			current = nil
+		case "UNG", "UNE", "UNZ":
+		default:
+			if current != nil {
+				current.Segments = append(current.Segments, s)
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%w: message %s has no UNT segment", ErrMalformed, current.ControlNumber)
+	}
+	return ic, nil
+}
+
+// x12FunctionalGroups maps transaction sets to their GS functional identifier
+var x12FunctionalGroups = map[string]string{
+	"850": "PO",
+	"855": "PR",
+	"856": "SH",
+	"810": "IN",
+	"997": "FA",
+}
+
+// Encode writes messages in one interchange. The messages of an X12
+// interchange must share a functional group. Values that contain a
+// delimiter are escaped in EDIFACT and have it replaced by a space in X12.
+func Encode(env Envelope, messages []Message) ([]byte, error) {
+	if !env.Standard.IsValid() {
+		return nil, fmt.Errorf("unsupported EDI standard %q", env.Standard)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("an interchange needs at least one message")
+	}
+	if env.Date.IsZero() {
+		env.Date = time.Now().UTC()
+	}
+
+	w := &writer{d: DefaultDelimiters(env.Standard)}
+	if env.Standard == StandardX12 {
+		return w.x12(env, messages)
+	}
+	return w.edifact(env, messages), nil
+}
+
+type writer struct {
+	d   Delimiters
+	buf bytes.Buffer
+}
+
+func (w *writer) escape(value string) string {
+	if w.d.Release == 0 {
+		return strings.Map(func(r rune) rune {
+			if r == rune(w.d.Segment) || r == rune(w.d.Element) || r == rune(w.d.Component) || r == '\r' || r == '\n' {
+				return ' '
+			}
+			return r
+		}, value)
+	}
+	var b strings.Builder
+	for _, r := range value {
+		if r == rune(w.d.Segment) || r == rune(w.d.Element) || r == rune(w.d.Component) || r == rune(w.d.Release) {
+			b.WriteByte(w.d.Release)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// write appends a segment, dropping trailing empty elements and components
+func (w *writer) write(s Segment) {
+	elements := s.Elements
+	for len(elements) > 0 && isEmpty(elements[len(elements)-1]) {
+		elements = elements[:len(elements)-1]
+	}
+	w.buf.WriteString(s.Tag)
+	for _, components := range elements {
+		w.buf.WriteByte(w.d.Element)
+		for len(components) > 1 && components[len(components)-1] == "" {
+			components = components[:len(components)-1]
+		}
+		for j, c := range components {
+			if j > 0 {
+				w.buf.WriteByte(w.d.Component)
+			}
+			w.buf.WriteString(w.escape(c))
+		}
+	}
+	w.buf.WriteByte(w.d.Segment)
+	w.buf.WriteByte('\n')
+}
+
+func isEmpty(components []string) bool {
+	for _, c := range components {
+		if c != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *writer) x12(env Envelope, messages []Message) ([]byte, error) {
+	group, ok := x12FunctionalGroups[messages[0].Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported X12 transaction set %s", messages[0].Type)
+	}
+	control, err := strconv.Atoi(env.ControlNumber)
+	if err != nil || control < 0 || control > 999999999 {
+		return nil, fmt.Errorf("X12 control number must be a number of up to 9 digits")
+	}
+	usage := "P"
+	if env.Test {
+		usage = "T"
+	}
+
+	// ISA is fixed width and written without dropping empty elements
+	isa := []string{
+		"00", fmt.Sprintf("%-10s", ""), "00", fmt.Sprintf("%-10s", ""),
+		fmt.Sprintf("%-2s", qualifierOr(env.SenderQualifier)), fmt.Sprintf("%-15.15s", w.escape(env.SenderID)),
+		fmt.Sprintf("%-2s", qualifierOr(env.ReceiverQualifier)), fmt.Sprintf("%-15.15s", w.escape(env.ReceiverID)),
+		env.Date.Format("060102"), env.Date.Format("1504"), "U", "00401",
+		fmt.Sprintf("%09d", control), "0", usage, string(w.d.Component),
+	}
+	w.buf.WriteString("ISA")
+	for _, e := range isa {
+		w.buf.WriteByte(w.d.Element)
+		w.buf.WriteString(e)
+	}
+	w.buf.WriteByte(w.d.Segment)
+	w.buf.WriteByte('\n')
+
+	w.write(NewSegment("GS", group, env.SenderID, env.ReceiverID, env.Date.Format("20060102"),
+		env.Date.Format("1504"), strconv.Itoa(control), "X", "004010"))
+	for i, m := range messages {
+		if x12FunctionalGroups[m.Type] != group {
+			return nil, fmt.Errorf("X12 transaction sets %s and %s cannot share a functional group", messages[0].Type, m.Type)
+		}
+		number := fmt.Sprintf("%04d", i+1)
+		w.write(NewSegment("ST", m.Type, number))
+		for _, s := range m.Segments {
+			w.write(s)
+		}
+		w.write(NewSegment("SE", strconv.Itoa(len(m.Segments)+2), number))
+	}
+	w.write(NewSegment("GE", strconv.Itoa(len(messages)), strconv.Itoa(control)))
+	w.write(NewSegment("IEA", "1", fmt.Sprintf("%09d", control)))
+	return w.buf.Bytes(), nil
+}
+
+func qualifierOr(qualifier string) string {
+	if qualifier == "" {
+		return "ZZ"
+	}
+	return qualifier
+}
+
+// edifactVersions are the directory versions messages are written in
+var edifactVersions = map[string][]string{
+	"ORDERS": {"ORDERS", "D", "96A", "UN"},
+	"DESADV": {"DESADV", "D", "96A", "UN"},
+	"INVOIC": {"INVOIC", "D", "96A", "UN"},
+}
+
+func (w *writer) edifact(env Envelope, messages []Message) []byte {
+	w.buf.WriteString("UNA:+.? '\n")
+
+	unb := Segment{Tag: "UNB", Elements: [][]string{
+		{"UNOC", "3"},
+		{env.SenderID, qualifierOr(env.SenderQualifier)},
+		{env.ReceiverID, qualifierOr(env.ReceiverQualifier)},
+		{env.Date.Format("060102"), env.Date.Format("1504")},
+		{env.ControlNumber},
+	}}
+	if env.Test {
+		unb.Elements = append(unb.Elements, []string{""}, []string{""}, []string{""}, []string{""}, []string{""}, []string{"1"})
+	}
+	w.write(unb)
+	for i, m := range messages {
+		number := strconv.Itoa(i + 1)
+		identifier, ok := edifactVersions[m.Type]
+		if !ok {
+			identifier = []string{m.Type, "D", "96A", "UN"}
+		}
+		w.write(Segment{Tag: "UNH", Elements: [][]string{{number}, identifier}})
+		for _, s := range m.Segments {
+			w.write(s)
+		}
+		w.write(NewSegment("UNT", strconv.Itoa(len(m.Segments)+2), number))
+	}
+	w.write(NewSegment("UNZ", strconv.Itoa(len(messages)), env.ControlNumber))
+	return w.buf.Bytes()
+}
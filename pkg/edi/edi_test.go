@@ -0,0 +1,201 @@
+package edi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const x12PurchaseOrder = `ISA*00*          *00*          *ZZ*BIGRETAIL      *ZZ*ALIEZE         *250301*1200*U*00401*000000042*0*T*>~
+GS*PO*BIGRETAIL*ALIEZE*20250301*1200*42*X*004010~
+ST*850*0001~
+BEG*00*SA*PO-7781**20250301~
+CUR*BY*USD~
+DTM*002*20250310~
+N1*ST*Store 114*92*114~
+N3*1 Market St~
+N4*Springfield*IL*62701*US~
+PO1*1*12*EA*4.5**BP*CUST-1*VP*WID-100~
+PID*F****Blue widget~
+PO1*2*3*CA*20**VP*GAD-7~
+SE*11*0001~
+GE*1*42~
+IEA*1*000000042~
+`
+
+func TestParse_X12PurchaseOrder(t *testing.T) {
+	ic, err := Parse([]byte(x12PurchaseOrder))
+	require.NoError(t, err)
+
+	assert.Equal(t, StandardX12, ic.Envelope.Standard)
+	assert.Equal(t, "BIGRETAIL", ic.Envelope.SenderID)
+	assert.Equal(t, "ZZ", ic.Envelope.SenderQualifier)
+	assert.Equal(t, "ALIEZE", ic.Envelope.ReceiverID)
+	assert.Equal(t, "42", ic.Envelope.ControlNumber)
+	assert.True(t, ic.Envelope.Test)
+	require.Len(t, ic.Messages, 1)
+
+	po, err := DecodePurchaseOrder(StandardX12, ic.Messages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "PO-7781", po.Number)
+	assert.Equal(t, "USD", po.Currency)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), po.Date)
+	require.NotNil(t, po.DeliveryDate)
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), *po.DeliveryDate)
+
+	shipTo := po.Party("ST")
+	require.NotNil(t, shipTo)
+	assert.Equal(t, Party{Qualifier: "ST", ID: "114", Name: "Store 114", Street: "1 Market St",
+		City: "Springfield", State: "IL", PostalCode: "62701", Country: "US"}, *shipTo)
+
+	require.Len(t, po.Lines, 2)
+	assert.Equal(t, LineItem{LineNumber: "1", ProductCode: "CUST-1", VendorCode: "WID-100",
+		Description: "Blue widget", Quantity: 12, UOM: "EA", UnitPrice: 4.5}, po.Lines[0])
+	assert.Equal(t, "GAD-7", po.Lines[1].ProductCode)
+	assert.Equal(t, 3.0, po.Lines[1].Quantity)
+}
+
+func TestParse_EDIFACTOrders(t *testing.T) {
+	data := "UNA:+.? '\n" +
+		"UNB+UNOC:3+5412345000013:14+ALIEZE:ZZ+250301:1200+77'\n" +
+		"UNH+1+ORDERS:D:96A:UN'\n" +
+		"BGM+220+PO?+991+9'\n" +
+		"DTM+137:20250301:102'\n" +
+		"DTM+2:20250310:102'\n" +
+		"CUX+2:EUR:9'\n" +
+		"NAD+DP+5412345000020::9++Depot Nord+Rue 1+Lille++59000+FR'\n" +
+		"LIN+1++4000862141404:EN'\n" +
+		"PIA+1+WID-100:SA'\n" +
+		"IMD+F++:::Widget?: blue'\n" +
+		"QTY+21:48:PCE'\n" +
+		"PRI+AAA:3.25'\n" +
+		"UNT+12+1'\n" +
+		"UNZ+1+77'\n"
+
+	ic, err := Parse([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, StandardEDIFACT, ic.Envelope.Standard)
+	assert.Equal(t, "5412345000013", ic.Envelope.SenderID)
+	assert.Equal(t, "14", ic.Envelope.SenderQualifier)
+	assert.Equal(t, "77", ic.Envelope.ControlNumber)
+	assert.False(t, ic.Envelope.Test)
+	require.Len(t, ic.Messages, 1)
+
+	po, err := DecodePurchaseOrder(StandardEDIFACT, ic.Messages[0])
+	require.NoError(t, err)
+	assert.Equal(t, "PO+991", po.Number)
+	assert.Equal(t, "EUR", po.Currency)
+	require.NotNil(t, po.DeliveryDate)
+
+	depot := po.ShipTo()
+	require.NotNil(t, depot)
+	assert.Equal(t, "Depot Nord", depot.Name)
+	assert.Equal(t, "Lille", depot.City)
+
+	require.Len(t, po.Lines, 1)
+	assert.Equal(t, LineItem{LineNumber: "1", ProductCode: "4000862141404", VendorCode: "WID-100",
+		Description: "Widget: blue", Quantity: 48, UOM: "PCE", UnitPrice: 3.25}, po.Lines[0])
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown envelope", "HDR*1~"},
+		{"short ISA", "ISA*00*~"},
+		{"wrong segment count", strings.Replace(x12PurchaseOrder, "SE*11*0001", "SE*9*0001", 1)},
+		{"unterminated transaction set", strings.Replace(x12PurchaseOrder, "SE*11*0001~\n", "", 1)},
+		{"EDIFACT without UNB", "UNA:+.? '\nUNH+1+ORDERS:D:96A:UN'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
+
+func TestDecodePurchaseOrder_RequiresLines(t *testing.T) {
+	_, err := DecodePurchaseOrder(StandardX12, Message{Type: "850", Segments: []Segment{NewSegment("BEG", "00", "SA", "PO-1")}})
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	_, err = DecodePurchaseOrder(StandardX12, Message{Type: "810"})
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	date := time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)
+	notice := ShipNotice{
+		ShipmentID:     "WH/OUT/00012",
+		PurchaseOrder:  "PO-7781",
+		ShipDate:       date,
+		CarrierCode:    "UPSN",
+		CarrierName:    "UPS",
+		TrackingNumber: "1Z999",
+		ShipTo:         Party{Qualifier: "ST", ID: "114", Name: "Store 114"},
+		Lines:          []LineItem{{LineNumber: "1", ProductCode: "CUST-1", VendorCode: "WID-100", Quantity: 12, UOM: "EA"}},
+	}
+	invoice := Invoice{
+		Number:        "INV/2025/0031",
+		Date:          date,
+		PurchaseOrder: "PO-7781",
+		Currency:      "USD",
+		BillTo:        Party{Qualifier: "BT", Name: "Big Retail*Inc"},
+		Lines:         []LineItem{{LineNumber: "1", ProductCode: "CUST-1", Description: "Blue widget", Quantity: 12, UOM: "EA", UnitPrice: 4.5}},
+		TotalAmount:   54,
+	}
+
+	for _, standard := range []Standard{StandardX12, StandardEDIFACT} {
+		t.Run(string(standard), func(t *testing.T) {
+			env := Envelope{Standard: standard, SenderID: "ALIEZE", ReceiverID: "BIGRETAIL", ControlNumber: "1001", Date: date, Test: true}
+
+			for _, m := range []Message{ShipNoticeMessage(standard, notice), InvoiceMessage(standard, invoice)} {
+				data, err := Encode(env, []Message{m})
+				require.NoError(t, err)
+
+				ic, err := Parse(data)
+				require.NoError(t, err, string(data))
+				assert.Equal(t, "ALIEZE", ic.Envelope.SenderID)
+				assert.Equal(t, "BIGRETAIL", ic.Envelope.ReceiverID)
+				assert.Equal(t, "1001", ic.Envelope.ControlNumber)
+				assert.True(t, ic.Envelope.Test)
+				assert.Equal(t, date, ic.Envelope.Date)
+				require.Len(t, ic.Messages, 1)
+				assert.Equal(t, m.Type, ic.Messages[0].Type)
+				assert.Len(t, ic.Messages[0].Segments, len(m.Segments))
+			}
+		})
+	}
+}
+
+func TestEncode_Escaping(t *testing.T) {
+	m := Message{Type: "INVOIC", Segments: []Segment{NewSegment("FTX", "AAI", "", "", "Rate: 5+ units? 'now'")}}
+	data, err := Encode(Envelope{Standard: StandardEDIFACT, SenderID: "A", ReceiverID: "B", ControlNumber: "1"}, []Message{m})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "FTX+AAI+++Rate?: 5?+ units?? ?'now?''")
+
+	ic, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Rate: 5+ units? 'now'", ic.Messages[0].Segments[0].Element(4))
+
+	m = Message{Type: "810", Segments: []Segment{NewSegment("N1", "BT", "Big Retail*Inc~")}}
+	data, err = Encode(Envelope{Standard: StandardX12, SenderID: "A", ReceiverID: "B", ControlNumber: "1"}, []Message{m})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "N1*BT*Big Retail Inc ~")
+}
+
+func TestEncode_Rejects(t *testing.T) {
+	m := Message{Type: "856"}
+	_, err := Encode(Envelope{Standard: StandardX12, ControlNumber: "ABC"}, []Message{m})
+	assert.Error(t, err)
+
+	_, err = Encode(Envelope{Standard: StandardX12, ControlNumber: "1"}, []Message{m, {Type: "810"}})
+	assert.Error(t, err)
+
+	_, err = Encode(Envelope{Standard: "xml", ControlNumber: "1"}, []Message{m})
+	assert.Error(t, err)
+}
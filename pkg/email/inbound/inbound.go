@@ -0,0 +1,541 @@
+// Package inbound parses the webhook posts email providers make for received
+// emails into one message shape, with the headers needed to thread replies.
+package inbound
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider is an email provider that posts received emails to a webhook
+type Provider string
+
+const (
+	// SendGrid is SendGrid Inbound Parse, in default or raw mode
+	SendGrid Provider = "sendgrid"
+	// Mailgun is a Mailgun route forwarding to a URL
+	Mailgun Provider = "mailgun"
+	// SES is an SES receipt rule publishing to an SNS topic with an
+	// HTTPS subscription
+	SES Provider = "ses"
+)
+
+// IsValid reports whether the provider is known
+func (p Provider) IsValid() bool {
+	switch p {
+	case SendGrid, Mailgun, SES:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrInvalid is returned for webhook payloads that cannot be parsed
+	ErrInvalid = errors.New("invalid inbound email")
+	// ErrSignature is returned when a Mailgun post fails its signature check
+	ErrSignature = errors.New("inbound email signature mismatch")
+)
+
+// maxFormMemory bounds the attachment bytes a webhook form keeps in memory;
+// the rest spills to temporary files that are removed after parsing
+const maxFormMemory = 8 << 20
+
+// mailgunSignatureAge is how old a Mailgun signature's timestamp may be
+const mailgunSignatureAge = 15 * time.Minute
+
+// Message is an email received through a provider webhook
+type Message struct {
+	// MessageID, InReplyTo and References are message ids without angle brackets
+	MessageID  string    `json:"message_id,omitempty"`
+	InReplyTo  string    `json:"in_reply_to,omitempty"`
+	References []string  `json:"references,omitempty"`
+	From       string    `json:"from"`
+	FromName   string    `json:"from_name,omitempty"`
+	To         []string  `json:"to,omitempty"`
+	Subject    string    `json:"subject"`
+	Text       string    `json:"text,omitempty"`
+	HTML       string    `json:"html,omitempty"`
+	Date       time.Time `json:"date"`
+	// AutoSubmitted is set for auto-replies, bounces and bulk mail
+	AutoSubmitted bool `json:"auto_submitted"`
+}
+
+// Thread returns the ids of the messages this one replies to, the direct
+// parent first
+func (m *Message) Thread() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	add(m.InReplyTo)
+	for i := len(m.References) - 1; i >= 0; i-- {
+		add(m.References[i])
+	}
+	return ids
+}
+
+// Parse parses a provider's webhook post. signingKey, when set,
+// verifies Mailgun posts. An SES subscription confirmation returns no message
+// and the URL that confirms the subscription.
+func Parse(provider Provider, contentType string, body []byte, signingKey string, now time.Time) (*Message, string, error) {
+	switch provider {
+	case SendGrid:
+		msg, err := parseSendGrid(contentType, body)
+		return msg, "", err
+	case Mailgun:
+		msg, err := parseMailgun(contentType, body, signingKey, now)
+		return msg, "", err
+	case SES:
+		return parseSES(body)
+	}
+	return nil, "", fmt.Errorf("%w: unknown provider %q", ErrInvalid, provider)
+}
+
+func parseSendGrid(contentType string, body []byte) (*Message, error) {
+	form, err := parseForm(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Raw mode posts the whole MIME message
+	if raw := form.Get("email"); raw != "" {
+		return ParseMIME([]byte(raw))
+	}
+
+	msg := &Message{
+		Subject: form.Get("subject"),
+		Text:    form.Get("text"),
+		HTML:    form.Get("html"),
+	}
+	if err := setFrom(msg, form.Get("from")); err != nil {
+		return nil, err
+	}
+	msg.To = addressList(form.Get("to"))
+
+	if headers := form.Get("headers"); headers != "" {
+		header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(headers + "\r\n\r\n"))).ReadMIMEHeader()
+		if err != nil && len(header) == 0 {
+			return nil, fmt.Errorf("%w: unreadable headers", ErrInvalid)
+		}
+		applyHeaders(msg, mail.Header(header))
+	}
+	return finish(msg), nil
+}
+
+func parseMailgun(contentType string, body []byte, signingKey string, now time.Time) (*Message, error) {
+	form, err := parseForm(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if signingKey != "" {
+		if err := verifyMailgun(form, signingKey, now); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &Message{
+		MessageID: trimID(form.Get("Message-Id")),
+		InReplyTo: trimID(form.Get("In-Reply-To")),
+		Subject:   form.Get("subject"),
+		Text:      form.Get("body-plain"),
+		HTML:      form.Get("body-html"),
+	}
+	if err := setFrom(msg, form.Get("from")); err != nil {
+		if err := setFrom(msg, form.Get("sender")); err != nil {
+			return nil, err
+		}
+	}
+	msg.To = addressList(form.Get("recipient"))
+	msg.References = idList(form.Get("References"))
+
+	var headers [][2]string
+	if err := json.Unmarshal([]byte(form.Get("message-headers")), &headers); err == nil {
+		header := make(mail.Header)
+		for _, h := range headers {
+			key := textproto.CanonicalMIMEHeaderKey(h[0])
+			header[key] = append(header[key], h[1])
+		}
+		applyHeaders(msg, header)
+	}
+	if msg.Date.IsZero() {
+		if ts, err := strconv.ParseInt(form.Get("timestamp"), 10, 64); err == nil {
+			msg.Date = time.Unix(ts, 0).UTC()
+		}
+	}
+	return finish(msg), nil
+}
+
+// verifyMailgun checks the HMAC-SHA256 of timestamp and token that Mailgun
+// signs every post with
+func verifyMailgun(form url.Values, signingKey string, now time.Time) error {
+	timestamp, token := form.Get("timestamp"), form.Get("token")
+	signature, err := hex.DecodeString(form.Get("signature"))
+	if err != nil || timestamp == "" || token == "" {
+		return ErrSignature
+	}
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp + token))
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return ErrSignature
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > mailgunSignatureAge || age < -mailgunSignatureAge {
+		return fmt.Errorf("%w: stale timestamp", ErrSignature)
+	}
+	return nil
+}
+
+type snsEnvelope struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		Timestamp     time.Time `json:"timestamp"`
+		Source        string    `json:"source"`
+		Destination   []string  `json:"destination"`
+		CommonHeaders struct {
+			From      []string `json:"from"`
+			To        []string `json:"to"`
+			Subject   string   `json:"subject"`
+			MessageID string   `json:"messageId"`
+		} `json:"commonHeaders"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"mail"`
+	Receipt struct {
+		Action struct {
+			Encoding string `json:"encoding"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+func parseSES(body []byte) (*Message, string, error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, "", fmt.Errorf("%w: not an SNS message", ErrInvalid)
+	}
+
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		// Only confirm against SNS itself, never an address from the payload
+		u, err := url.Parse(envelope.SubscribeURL)
+		if err != nil || u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+			return nil, "", fmt.Errorf("%w: subscribe URL is not an SNS endpoint", ErrInvalid)
+		}
+		return nil, envelope.SubscribeURL, nil
+	case "Notification":
+	default:
+		return nil, "", fmt.Errorf("%w: unexpected SNS message type %q", ErrInvalid, envelope.Type)
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(envelope.Message), &notification); err != nil {
+		return nil, "", fmt.Errorf("%w: not an SES notification", ErrInvalid)
+	}
+	if notification.NotificationType != "Received" {
+		return nil, "", fmt.Errorf("%w: unexpected SES notification %q", ErrInvalid, notification.NotificationType)
+	}
+
+	if notification.Content != "" {
+		raw := []byte(notification.Content)
+		if strings.EqualFold(notification.Receipt.Action.Encoding, "BASE64") {
+			decoded, err := base64.StdEncoding.DecodeString(notification.Content)
+			if err != nil {
+				return nil, "", fmt.Errorf("%w: undecodable content", ErrInvalid)
+			}
+			raw = decoded
+		}
+		msg, err := ParseMIME(raw)
+		return msg, "", err
+	}
+
+	// Without the content only the headers are known
+	mailInfo := notification.Mail
+	msg := &Message{
+		MessageID: trimID(mailInfo.CommonHeaders.MessageID),
+		Subject:   mailInfo.CommonHeaders.Subject,
+		To:        mailInfo.CommonHeaders.To,
+		Date:      mailInfo.Timestamp,
+	}
+	from := mailInfo.Source
+	if len(mailInfo.CommonHeaders.From) > 0 {
+		from = mailInfo.CommonHeaders.From[0]
+	}
+	if err := setFrom(msg, from); err != nil {
+		return nil, "", err
+	}
+	header := make(mail.Header)
+	for _, h := range mailInfo.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(h.Name)
+		header[key] = append(header[key], h.Value)
+	}
+	applyHeaders(msg, header)
+	return finish(msg), "", nil
+}
+
+// ParseMIME parses a raw RFC 5322 message, taking the first text/plain and
+// text/html parts of its body
+func ParseMIME(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	msg := &Message{}
+	if err := setFrom(msg, m.Header.Get("From")); err != nil {
+		return nil, err
+	}
+	if to, err := m.Header.AddressList("To"); err == nil {
+		for _, addr := range to {
+			msg.To = append(msg.To, addr.Address)
+		}
+	}
+	applyHeaders(msg, m.Header)
+
+	if err := readBody(msg, textproto.MIMEHeader(m.Header), m.Body, 0); err != nil {
+		return nil, err
+	}
+	return finish(msg), nil
+}
+
+// readBody walks a MIME part, nested multiparts included
+func readBody(msg *Message, header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth > 5 || params["boundary"] == "" {
+			return nil
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			if err := readBody(msg, part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return nil
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil
+	}
+	if (mediaType == "text/plain" && msg.Text != "") || (mediaType == "text/html" && msg.HTML != "") {
+		return nil
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	if mediaType == "text/plain" {
+		msg.Text = string(content)
+	} else {
+		msg.HTML = string(content)
+	}
+	return nil
+}
+
+// applyHeaders reads the threading, date and auto-reply headers
+func applyHeaders(msg *Message, header mail.Header) {
+	decoder := new(mime.WordDecoder)
+	if msg.MessageID == "" {
+		msg.MessageID = trimID(header.Get("Message-Id"))
+	}
+	if msg.InReplyTo == "" {
+		msg.InReplyTo = trimID(firstID(header.Get("In-Reply-To")))
+	}
+	if len(msg.References) == 0 {
+		msg.References = idList(header.Get("References"))
+	}
+	if msg.Subject == "" {
+		if subject, err := decoder.DecodeHeader(header.Get("Subject")); err == nil {
+			msg.Subject = subject
+		}
+	}
+	if msg.Date.IsZero() {
+		if date, err := header.Date(); err == nil {
+			msg.Date = date.UTC()
+		}
+	}
+
+	autoSubmitted := strings.ToLower(header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(header.Get("Precedence"))
+	msg.AutoSubmitted = (autoSubmitted != "" && autoSubmitted != "no") ||
+		precedence == "bulk" || precedence == "junk" || precedence == "auto_reply" ||
+		header.Get("X-Autoreply") != "" || header.Get("X-Autorespond") != ""
+}
+
+func setFrom(msg *Message, from string) error {
+	addr, err := (&mail.AddressParser{WordDecoder: new(mime.WordDecoder)}).Parse(from)
+	if err != nil {
+		return fmt.Errorf("%w: unreadable sender %q", ErrInvalid, from)
+	}
+	msg.From = strings.ToLower(addr.Address)
+	msg.FromName = addr.Name
+	return nil
+}
+
+func finish(msg *Message) *Message {
+	msg.Subject = strings.TrimSpace(msg.Subject)
+	msg.Text = strings.ReplaceAll(msg.Text, "\r\n", "\n")
+	if msg.Text == "" && msg.HTML != "" {
+		msg.Text = htmlToText(msg.HTML)
+	}
+	return msg
+}
+
+func addressList(value string) []string {
+	list, err := mail.ParseAddressList(value)
+	if err != nil {
+		return nil
+	}
+	addresses := make([]string, len(list))
+	for i, addr := range list {
+		addresses[i] = strings.ToLower(addr.Address)
+	}
+	return addresses
+}
+
+func trimID(id string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(id), "<>"))
+}
+
+func firstID(value string) string {
+	if ids := idList(value); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+func idList(value string) []string {
+	var ids []string
+	for _, field := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+		if id := trimID(field); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// parseForm reads a urlencoded or multipart webhook form
+func parseForm(contentType string, body []byte) (url.Values, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unreadable content type", ErrInvalid)
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return values, nil
+	case "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(maxFormMemory)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		defer form.RemoveAll()
+		return url.Values(form.Value), nil
+	}
+	return nil, fmt.Errorf("%w: unexpected content type %s", ErrInvalid, mediaType)
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlDrop   = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText reduces an HTML body to its text for messages without a plain
+// text part
+func htmlToText(html string) string {
+	text := htmlDrop.ReplaceAllString(html, "")
+	text = htmlBreaks.ReplaceAllString(text, "\n")
+	text = htmlTags.ReplaceAllString(text, "")
+	text = strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'").Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+var replyHeader = regexp.MustCompile(`(?i)^(on .+ wrote:|le .+ a écrit\s?:|-+\s*original message\s*-+|from: .+)$`)
+
+// StripReply returns the new content of a reply, without the quoted message
+// it answers and without the signature delimited by "-- "
+func StripReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if replyHeader.MatchString(trimmed) || line == "-- " {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	stripped := strings.TrimSpace(strings.Join(kept, "\n"))
+	if stripped == "" {
+		return strings.TrimSpace(text)
+	}
+	return stripped
+}
@@ -0,0 +1,165 @@
+package inbound
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rawReply = "From: =?UTF-8?Q?Jos=C3=A9_Garc=C3=ADa?= <Jose@Example.com>\r\n" +
+	"To: sales@acme.test\r\n" +
+	"Subject: Re: Pricing for 40 seats\r\n" +
+	"Date: Mon, 31 Mar 2025 09:15:00 +0200\r\n" +
+	"Message-ID: <reply-2@example.com>\r\n" +
+	"In-Reply-To: <quote-1@acme.test>\r\n" +
+	"References: <inquiry-0@example.com> <quote-1@acme.test>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Sounds good, let=E2=80=99s talk Thursday.\r\n" +
+	"\r\n" +
+	"On Fri, Mar 28, 2025 at 4:00 PM Sales <sales@acme.test> wrote:\r\n" +
+	"> Here is our quote.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Sounds good</p>\r\n" +
+	"--b1--\r\n"
+
+func multipartForm(t *testing.T, fields map[string]string) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		require.NoError(t, w.WriteField(name, value))
+	}
+	require.NoError(t, w.Close())
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestParseMIMEReadsThreadingHeadersAndBody(t *testing.T) {
+	msg, err := ParseMIME([]byte(rawReply))
+	require.NoError(t, err)
+
+	assert.Equal(t, "jose@example.com", msg.From)
+	assert.Equal(t, "José García", msg.FromName)
+	assert.Equal(t, []string{"sales@acme.test"}, msg.To)
+	assert.Equal(t, "Re: Pricing for 40 seats", msg.Subject)
+	assert.Equal(t, "reply-2@example.com", msg.MessageID)
+	assert.Equal(t, "quote-1@acme.test", msg.InReplyTo)
+	assert.Equal(t, []string{"quote-1@acme.test", "inquiry-0@example.com"}, msg.Thread())
+	assert.Contains(t, msg.Text, "let’s talk Thursday")
+	assert.Equal(t, "<p>Sounds good</p>", msg.HTML)
+	assert.Equal(t, time.Date(2025, 3, 31, 7, 15, 0, 0, time.UTC), msg.Date)
+	assert.False(t, msg.AutoSubmitted)
+	assert.Equal(t, "Sounds good, let’s talk Thursday.", StripReply(msg.Text))
+}
+
+func TestParseSendGridDefaultMode(t *testing.T) {
+	contentType, body := multipartForm(t, map[string]string{
+		"from":    "Jane Doe <jane@example.com>",
+		"to":      "leads@acme.test",
+		"subject": "Demo request",
+		"html":    "<html><body><p>Hi,</p><p>Can we get a demo?</p></body></html>",
+		"headers": "Message-ID: <abc@example.com>\nAuto-Submitted: auto-replied\nDate: Mon, 31 Mar 2025 09:15:00 +0000\n",
+	})
+
+	msg, subscribeURL, err := Parse(SendGrid, contentType, body, "", time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, subscribeURL)
+	assert.Equal(t, "jane@example.com", msg.From)
+	assert.Equal(t, "Jane Doe", msg.FromName)
+	assert.Equal(t, "abc@example.com", msg.MessageID)
+	assert.Equal(t, "Hi,\nCan we get a demo?", msg.Text)
+	assert.True(t, msg.AutoSubmitted)
+}
+
+func TestParseSendGridRawMode(t *testing.T) {
+	contentType, body := multipartForm(t, map[string]string{"email": rawReply})
+
+	msg, _, err := Parse(SendGrid, contentType, body, "", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "reply-2@example.com", msg.MessageID)
+}
+
+func TestParseMailgunVerifiesSignature(t *testing.T) {
+	now := time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("signing-key"))
+	mac.Write([]byte(timestamp + "tok"))
+
+	form := url.Values{
+		"from":        {"Jane Doe <jane@example.com>"},
+		"recipient":   {"leads@acme.test"},
+		"subject":     {"Re: Demo"},
+		"body-plain":  {"Thanks!"},
+		"Message-Id":  {"<m-2@example.com>"},
+		"In-Reply-To": {"<m-1@acme.test>"},
+		"timestamp":   {timestamp},
+		"token":       {"tok"},
+		"signature":   {hex.EncodeToString(mac.Sum(nil))},
+	}
+	body := []byte(form.Encode())
+
+	msg, _, err := Parse(Mailgun, "application/x-www-form-urlencoded", body, "signing-key", now)
+	require.NoError(t, err)
+	assert.Equal(t, "m-2@example.com", msg.MessageID)
+	assert.Equal(t, "m-1@acme.test", msg.InReplyTo)
+	assert.Equal(t, now, msg.Date)
+
+	_, _, err = Parse(Mailgun, "application/x-www-form-urlencoded", body, "other-key", now)
+	assert.ErrorIs(t, err, ErrSignature)
+
+	_, _, err = Parse(Mailgun, "application/x-www-form-urlencoded", body, "signing-key", now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrSignature)
+}
+
+func TestParseSESNotification(t *testing.T) {
+	notification, err := json.Marshal(map[string]interface{}{
+		"notificationType": "Received",
+		"receipt":          map[string]interface{}{"action": map[string]string{"type": "SNS", "encoding": "BASE64"}},
+		"content":          base64.StdEncoding.EncodeToString([]byte(rawReply)),
+	})
+	require.NoError(t, err)
+	body, err := json.Marshal(map[string]string{"Type": "Notification", "Message": string(notification)})
+	require.NoError(t, err)
+
+	msg, _, err := Parse(SES, "text/plain", body, "", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "jose@example.com", msg.From)
+	assert.Equal(t, "reply-2@example.com", msg.MessageID)
+}
+
+func TestParseSESSubscriptionConfirmation(t *testing.T) {
+	confirm := `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription&Token=x"}`
+	msg, subscribeURL, err := Parse(SES, "text/plain", []byte(confirm), "", time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+	assert.True(t, strings.HasPrefix(subscribeURL, "https://sns.eu-west-1.amazonaws.com/"))
+
+	forged := `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://attacker.test/?sns.eu-west-1.amazonaws.com"}`
+	_, _, err = Parse(SES, "text/plain", []byte(forged), "", time.Now())
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestParseRejectsUnreadableSender(t *testing.T) {
+	contentType, body := multipartForm(t, map[string]string{"from": "not an address", "subject": "Hi"})
+	_, _, err := Parse(SendGrid, contentType, body, "", time.Now())
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,162 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// sendGridEndpoint is the SendGrid v3 mail send API
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridService implements Service interface using the SendGrid v3 API
+type SendGridService struct {
+	config      *SendGridConfig
+	defaultFrom string
+	endpoint    string
+	client      *http.Client
+}
+
+// NewSendGridService creates a new SendGrid email service
+func NewSendGridService(config *SendGridConfig, defaultFrom string) (*SendGridService, error) {
+	if config == nil {
+		return nil, fmt.Errorf("SendGrid configuration is required")
+	}
+
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("SendGrid API key is required")
+	}
+
+	return &SendGridService{
+		config:      config,
+		defaultFrom: defaultFrom,
+		endpoint:    sendGridEndpoint,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	CC  []sendGridAddress `json:"cc,omitempty"`
+	BCC []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content  string `json:"content"`
+	Type     string `json:"type,omitempty"`
+	Filename string `json:"filename"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+func sendGridAddresses(emails []string) []sendGridAddress {
+	var addresses []sendGridAddress
+	for _, email := range emails {
+		addresses = append(addresses, sendGridAddress{Email: email})
+	}
+	return addresses
+}
+
+// Send sends an email
+func (s *SendGridService) Send(ctx context.Context, msg *Email) error {
+	from := msg.From
+	if from == "" {
+		from = s.defaultFrom
+	}
+	if from == "" {
+		return fmt.Errorf("from address is required")
+	}
+
+	if len(msg.To) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	message := sendGridMessage{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(msg.To),
+			CC:  sendGridAddresses(msg.CC),
+			BCC: sendGridAddresses(msg.BCC),
+		}},
+		From:    sendGridAddress{Email: from},
+		Subject: msg.Subject,
+		Headers: msg.Headers,
+	}
+
+	// SendGrid expects the plain text part before the HTML part
+	if msg.Body != "" {
+		message.Content = append(message.Content, sendGridContent{Type: "text/plain", Value: msg.Body})
+	}
+	if msg.HTML != "" {
+		message.Content = append(message.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+	if len(message.Content) == 0 {
+		return fmt.Errorf("email body is required")
+	}
+
+	for _, att := range msg.Attachments {
+		data := att.Data
+		if data == nil && att.Reader != nil {
+			var err error
+			data, err = io.ReadAll(att.Reader)
+			if err != nil {
+				return fmt.Errorf("failed to read attachment: %w", err)
+			}
+		}
+		message.Attachments = append(message.Attachments, sendGridAttachment{
+			Content:  base64.StdEncoding.EncodeToString(data),
+			Type:     att.ContentType,
+			Filename: att.Filename,
+		})
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("SendGrid rejected email with status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// SendTemplate sends a template-based email
+func (s *SendGridService) SendTemplate(ctx context.Context, opts *TemplateEmailOptions) error {
+	// Note: Template rendering should be done by the caller
+	return fmt.Errorf("template email not implemented in SendGrid service - render template first")
+}
@@ -0,0 +1,60 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendGridSendPostsMessage(t *testing.T) {
+	var got sendGridMessage
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	svc, err := NewSendGridService(&SendGridConfig{APIKey: "key"}, "sales@acme.test")
+	require.NoError(t, err)
+	svc.endpoint = server.URL
+
+	err = svc.Send(context.Background(), &Email{
+		To:          []string{"buyer@example.com"},
+		Subject:     "Your quote",
+		Body:        "See attached",
+		HTML:        "<p>See attached</p>",
+		Attachments: []*Attachment{{Filename: "quote.pdf", ContentType: "application/pdf", Data: []byte("pdf")}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer key", auth)
+	assert.Equal(t, "sales@acme.test", got.From.Email)
+	require.Len(t, got.Personalizations, 1)
+	assert.Equal(t, []sendGridAddress{{Email: "buyer@example.com"}}, got.Personalizations[0].To)
+	assert.Equal(t, []sendGridContent{{Type: "text/plain", Value: "See attached"}, {Type: "text/html", Value: "<p>See attached</p>"}}, got.Content)
+	require.Len(t, got.Attachments, 1)
+	assert.Equal(t, "cGRm", got.Attachments[0].Content)
+}
+
+func TestSendGridSendReportsRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":[{"message":"bad sender"}]}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	svc, err := NewSendGridService(&SendGridConfig{APIKey: "key"}, "sales@acme.test")
+	require.NoError(t, err)
+	svc.endpoint = server.URL
+
+	err = svc.Send(context.Background(), &Email{To: []string{"buyer@example.com"}, Body: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.Contains(t, err.Error(), "bad sender")
+}
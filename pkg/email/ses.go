@@ -0,0 +1,32 @@
+package email
+
+import (
+	"fmt"
+)
+
+// sesSMTPPort is the STARTTLS port of the SES SMTP interface
+const sesSMTPPort = 587
+
+// NewSESService creates an email service that sends through the SMTP
+// interface of AWS SES in the configured region. The access and secret keys
+// are the SES SMTP credentials, which differ from the IAM access keys.
+func NewSESService(config *SESConfig, defaultFrom string) (*SMTPService, error) {
+	if config == nil {
+		return nil, fmt.Errorf("SES configuration is required")
+	}
+
+	if config.Region == "" {
+		return nil, fmt.Errorf("SES region is required")
+	}
+
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("SES SMTP credentials are required")
+	}
+
+	return NewSMTPService(&SMTPConfig{
+		Host:     fmt.Sprintf("email-smtp.%s.amazonaws.com", config.Region),
+		Port:     sesSMTPPort,
+		Username: config.AccessKey,
+		Password: config.SecretKey,
+	}, defaultFrom)
+}
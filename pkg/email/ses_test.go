@@ -0,0 +1,40 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSESServiceUsesRegionalSMTPInterface(t *testing.T) {
+	svc, err := NewSESService(&SESConfig{Region: "eu-west-1", AccessKey: "user", SecretKey: "pass"}, "sales@acme.test")
+	require.NoError(t, err)
+
+	assert.Equal(t, "email-smtp.eu-west-1.amazonaws.com", svc.config.Host)
+	assert.Equal(t, 587, svc.config.Port)
+	assert.Equal(t, "user", svc.config.Username)
+	assert.Equal(t, "pass", svc.config.Password)
+}
+
+func TestSESServiceRequiresRegionAndCredentials(t *testing.T) {
+	for _, config := range []*SESConfig{
+		nil,
+		{AccessKey: "user", SecretKey: "pass"},
+		{Region: "eu-west-1"},
+		{Region: "eu-west-1", AccessKey: "user"},
+	} {
+		_, err := NewSESService(config, "sales@acme.test")
+		assert.Error(t, err)
+	}
+}
+
+func TestNewServiceSelectsProvider(t *testing.T) {
+	svc, err := NewService(&Config{Provider: "sendgrid", SendGrid: &SendGridConfig{APIKey: "key"}})
+	require.NoError(t, err)
+	assert.IsType(t, &SendGridService{}, svc)
+
+	svc, err = NewService(&Config{Provider: "ses", SES: &SESConfig{Region: "eu-west-1", AccessKey: "user", SecretKey: "pass"}})
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPService{}, svc)
+}
@@ -0,0 +1,126 @@
+// Package entitlements lets modules check an organization's subscription
+// plan before creating what the plan limits, and report plan errors with a
+// hint at the plan to upgrade to
+package entitlements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Limit is a quantity a plan caps
+type Limit string
+
+const (
+	LimitUsers       Limit = "users"
+	LimitLeads       Limit = "leads"
+	LimitAutomations Limit = "automations"
+	LimitAPIRate     Limit = "api_rate_per_minute"
+)
+
+// Features plans can include
+const (
+	FeatureSandbox = "sandbox"
+)
+
+// Checker checks an organization's plan
+type Checker interface {
+	// CheckLimit returns a *LimitError when adding more of limit would take
+	// the organization past its plan
+	CheckLimit(ctx context.Context, orgID uuid.UUID, limit Limit, adding int) error
+	// CheckFeature returns a *FeatureError when the organization's plan does
+	// not include the feature
+	CheckFeature(ctx context.Context, orgID uuid.UUID, feature string) error
+}
+
+// LimitError is returned when an organization has reached a limit of its plan
+type LimitError struct {
+	Limit     Limit
+	Plan      string
+	Max       int
+	Current   int
+	UpgradeTo string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("plan limit reached: the %s plan allows %d %s", e.Plan, e.Max, e.Limit)
+}
+
+// FeatureError is returned when an organization's plan does not include a
+// feature
+type FeatureError struct {
+	Feature   string
+	Plan      string
+	UpgradeTo string
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("feature not included: the %s plan does not include %s", e.Plan, e.Feature)
+}
+
+// UpgradeHint tells the user what to do about a plan error
+func UpgradeHint(upgradeTo string) string {
+	if upgradeTo == "" {
+		return "Contact support to raise your plan's limits."
+	}
+	return fmt.Sprintf("Upgrade to the %s plan to continue.", upgradeTo)
+}
+
+// errorResponse is the body of plan errors
+type errorResponse struct {
+	Error       string `json:"error"`
+	Code        string `json:"code"`
+	Plan        string `json:"plan"`
+	Limit       Limit  `json:"limit,omitempty"`
+	Max         *int   `json:"max,omitempty"`
+	Current     *int   `json:"current,omitempty"`
+	Feature     string `json:"feature,omitempty"`
+	UpgradeTo   string `json:"upgrade_to,omitempty"`
+	UpgradeHint string `json:"upgrade_hint"`
+}
+
+// WriteError writes a plan error, a limit reached as 402 Payment Required and
+// a feature not included as 403 Forbidden, and reports whether err was one
+func WriteError(w http.ResponseWriter, err error) bool {
+	var status int
+	var body errorResponse
+
+	var limitErr *LimitError
+	var featureErr *FeatureError
+	switch {
+	case errors.As(err, &limitErr):
+		status = http.StatusPaymentRequired
+		body = errorResponse{
+			Error:       limitErr.Error(),
+			Code:        "plan_limit_reached",
+			Plan:        limitErr.Plan,
+			Limit:       limitErr.Limit,
+			Max:         &limitErr.Max,
+			Current:     &limitErr.Current,
+			UpgradeTo:   limitErr.UpgradeTo,
+			UpgradeHint: UpgradeHint(limitErr.UpgradeTo),
+		}
+	case errors.As(err, &featureErr):
+		status = http.StatusForbidden
+		body = errorResponse{
+			Error:       featureErr.Error(),
+			Code:        "feature_not_included",
+			Plan:        featureErr.Plan,
+			Feature:     featureErr.Feature,
+			UpgradeTo:   featureErr.UpgradeTo,
+			UpgradeHint: UpgradeHint(featureErr.UpgradeTo),
+		}
+	default:
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+	return true
+}
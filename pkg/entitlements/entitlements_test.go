@@ -0,0 +1,49 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteErrorLimitReached(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := fmt.Errorf("create lead: %w", &LimitError{Limit: LimitLeads, Plan: "free", Max: 500, Current: 500, UpgradeTo: "starter"})
+
+	require.True(t, WriteError(w, err))
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "plan_limit_reached", body["code"])
+	assert.Equal(t, "leads", body["limit"])
+	assert.Equal(t, float64(500), body["max"])
+	assert.Equal(t, "starter", body["upgrade_to"])
+	assert.Equal(t, "Upgrade to the starter plan to continue.", body["upgrade_hint"])
+}
+
+func TestWriteErrorFeatureNotIncluded(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	require.True(t, WriteError(w, &FeatureError{Feature: FeatureSandbox, Plan: "enterprise"}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "feature_not_included", body["code"])
+	assert.Equal(t, "Contact support to raise your plan's limits.", body["upgrade_hint"])
+}
+
+func TestWriteErrorIgnoresOtherErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.False(t, WriteError(w, errors.New("boom")))
+	assert.False(t, WriteError(w, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+}
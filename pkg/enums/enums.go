@@ -0,0 +1,149 @@
+// Package enums describes the enumerations exposed by the API so clients
+// can list their values instead of hardcoding them. Each enumeration is
+// declared next to the Go type it describes, with a label per locale, the
+// values each one may move to where the enumeration is a state, and the
+// values that are deprecated.
+package enums
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the locale every label must be given in and the one
+// labels fall back to
+const DefaultLocale = "en"
+
+// Labels are the labels of a value by locale, such as "en" or "fr"
+type Labels map[string]string
+
+// Label returns the label for a locale, falling back from a regional
+// locale such as "fr-CA" to its language and then to the default locale
+func (l Labels) Label(locale string) string {
+	locale = strings.ToLower(locale)
+	if label, ok := l[locale]; ok {
+		return label
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		if label, ok := l[lang]; ok {
+			return label
+		}
+	}
+	return l[DefaultLocale]
+}
+
+// Value is a value of an enumeration
+type Value struct {
+	Value  string
+	Labels Labels
+	// Deprecated values are still accepted but should not be offered for
+	// new records; ReplacedBy names the value to use instead, if any
+	Deprecated bool
+	ReplacedBy string
+	// Transitions are the values a record may move to from this one. They
+	// are only set on enumerations that are states.
+	Transitions []string
+}
+
+// Enum is an enumeration
+type Enum struct {
+	// Name identifies the enumeration, such as "crm.lead_priority"
+	Name   string
+	Values []Value
+}
+
+// Has reports whether the value is one of the enumeration's
+func (e Enum) Has(value string) bool {
+	_, ok := e.Value(value)
+	return ok
+}
+
+// Value returns one of the enumeration's values
+func (e Enum) Value(value string) (Value, bool) {
+	for _, v := range e.Values {
+		if v.Value == value {
+			return v, true
+		}
+	}
+	return Value{}, false
+}
+
+// HasTransitions reports whether the enumeration is a state, whose values
+// list the values they may move to
+func (e Enum) HasTransitions() bool {
+	for _, v := range e.Values {
+		if v.Transitions != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransition reports whether a record may move from one value to
+// another. Staying on the same value is always allowed.
+func (e Enum) CanTransition(from, to string) bool {
+	if !e.Has(to) {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	v, ok := e.Value(from)
+	if !ok {
+		return false
+	}
+	for _, next := range v.Transitions {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the enumeration is consistent: its values are unique and
+// labeled in the default locale, and replacements and transitions name
+// its own values
+func (e Enum) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("enum has no name")
+	}
+	seen := make(map[string]bool, len(e.Values))
+	for _, v := range e.Values {
+		if seen[v.Value] {
+			return fmt.Errorf("enum %s: duplicate value %q", e.Name, v.Value)
+		}
+		seen[v.Value] = true
+		if v.Labels[DefaultLocale] == "" {
+			return fmt.Errorf("enum %s: value %q has no %s label", e.Name, v.Value, DefaultLocale)
+		}
+	}
+	for _, v := range e.Values {
+		if v.ReplacedBy != "" && !seen[v.ReplacedBy] {
+			return fmt.Errorf("enum %s: value %q is replaced by unknown value %q", e.Name, v.Value, v.ReplacedBy)
+		}
+		for _, next := range v.Transitions {
+			if !seen[next] {
+				return fmt.Errorf("enum %s: value %q moves to unknown value %q", e.Name, v.Value, next)
+			}
+		}
+	}
+	return nil
+}
+
+// Sorted returns the enumerations ordered by name, checking each is valid
+// and that no two share a name
+func Sorted(enums []Enum) ([]Enum, error) {
+	sorted := make([]Enum, len(enums))
+	copy(sorted, enums)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for i, e := range sorted {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+		if i > 0 && sorted[i-1].Name == e.Name {
+			return nil, fmt.Errorf("enum %s is declared twice", e.Name)
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,84 @@
+package enums
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStatus = Enum{Name: "test.status", Values: []Value{
+	{Value: "open", Labels: Labels{"en": "Open", "fr": "Ouvert"}, Transitions: []string{"closed"}},
+	{Value: "pending", Labels: Labels{"en": "Pending"}, Deprecated: true, ReplacedBy: "open", Transitions: []string{"open", "closed"}},
+	{Value: "closed", Labels: Labels{"en": "Closed", "fr": "Fermé", "fr-ca": "Fermé (CA)"}},
+}}
+
+func TestEnumTransitions(t *testing.T) {
+	assert.True(t, testStatus.HasTransitions())
+	assert.True(t, testStatus.CanTransition("open", "closed"))
+	assert.True(t, testStatus.CanTransition("closed", "closed"))
+	assert.False(t, testStatus.CanTransition("closed", "open"))
+	assert.False(t, testStatus.CanTransition("open", "unknown"))
+	assert.False(t, testStatus.CanTransition("", "open"))
+
+	assert.False(t, Enum{Name: "test.kind", Values: []Value{{Value: "a", Labels: Labels{"en": "A"}}}}.HasTransitions())
+}
+
+func TestSortedValidatesEnums(t *testing.T) {
+	kind := Enum{Name: "test.kind", Values: []Value{{Value: "a", Labels: Labels{"en": "A"}}}}
+	sorted, err := Sorted([]Enum{testStatus, kind})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.kind", "test.status"}, []string{sorted[0].Name, sorted[1].Name})
+
+	_, err = Sorted([]Enum{kind, kind})
+	assert.ErrorContains(t, err, "declared twice")
+
+	_, err = Sorted([]Enum{{Name: "test.bad", Values: []Value{{Value: "a", Labels: Labels{"fr": "A"}}}}})
+	assert.ErrorContains(t, err, "has no en label")
+
+	_, err = Sorted([]Enum{{Name: "test.bad", Values: []Value{{Value: "a", Labels: Labels{"en": "A"}, Transitions: []string{"b"}}}}})
+	assert.ErrorContains(t, err, `moves to unknown value "b"`)
+}
+
+func TestHandlerLocalizesLabels(t *testing.T) {
+	handler, err := NewHandler([]Enum{testStatus})
+	require.NoError(t, err)
+
+	get := func(target, acceptLanguage string) (string, []LocalizedEnum) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			Locale string          `json:"locale"`
+			Enums  []LocalizedEnum `json:"enums"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.Locale, body.Enums
+	}
+
+	locale, list := get("/api/v1/metadata/enums", "de;q=0.5, fr-CA, en;q=0.8")
+	assert.Equal(t, "fr-ca", locale)
+	require.Len(t, list, 1)
+	assert.Equal(t, LocalizedEnum{Name: "test.status", Stateful: true, Values: []LocalizedValue{
+		{Value: "open", Label: "Ouvert", Transitions: []string{"closed"}},
+		// Without a French label, pending falls back to English
+		{Value: "pending", Label: "Pending", Deprecated: true, ReplacedBy: "open", Transitions: []string{"open", "closed"}},
+		{Value: "closed", Label: "Fermé (CA)"},
+	}}, list[0])
+
+	locale, list = get("/api/v1/metadata/enums?locale=FR", "en")
+	assert.Equal(t, "fr", locale)
+	assert.Equal(t, "Fermé", list[0].Values[2].Label)
+
+	locale, list = get("/api/v1/metadata/enums", "")
+	assert.Equal(t, DefaultLocale, locale)
+	assert.Equal(t, "Closed", list[0].Values[2].Label)
+}
@@ -0,0 +1,102 @@
+package enums
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LocalizedValue is a value as listed to clients, labeled in one locale
+type LocalizedValue struct {
+	Value       string   `json:"value"`
+	Label       string   `json:"label"`
+	Deprecated  bool     `json:"deprecated"`
+	ReplacedBy  string   `json:"replaced_by,omitempty"`
+	Transitions []string `json:"transitions,omitempty"`
+}
+
+// LocalizedEnum is an enumeration as listed to clients. The values of
+// stateful enumerations list the values they may move to; those without
+// transitions are final.
+type LocalizedEnum struct {
+	Name     string           `json:"name"`
+	Stateful bool             `json:"stateful"`
+	Values   []LocalizedValue `json:"values"`
+}
+
+// Localize labels the enumerations in a locale
+func Localize(enums []Enum, locale string) []LocalizedEnum {
+	localized := make([]LocalizedEnum, len(enums))
+	for i, e := range enums {
+		values := make([]LocalizedValue, len(e.Values))
+		for j, v := range e.Values {
+			values[j] = LocalizedValue{
+				Value:       v.Value,
+				Label:       v.Labels.Label(locale),
+				Deprecated:  v.Deprecated,
+				ReplacedBy:  v.ReplacedBy,
+				Transitions: v.Transitions,
+			}
+		}
+		localized[i] = LocalizedEnum{Name: e.Name, Stateful: e.HasTransitions(), Values: values}
+	}
+	return localized
+}
+
+// RequestLocale returns the locale asked for by the locale query parameter,
+// or else the preferred language of the Accept-Language header, or else the
+// default locale
+func RequestLocale(r *http.Request) string {
+	if locale := strings.TrimSpace(r.URL.Query().Get("locale")); locale != "" {
+		return strings.ToLower(locale)
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	if best == "" {
+		return DefaultLocale
+	}
+	return strings.ToLower(best)
+}
+
+// Handler lists enumerations, labeled in the locale of the request
+type Handler struct {
+	enums []Enum
+}
+
+// NewHandler creates a handler listing the enumerations, which must be
+// valid and uniquely named
+func NewHandler(enums []Enum) (*Handler, error) {
+	sorted, err := Sorted(enums)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{enums: sorted}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	locale := RequestLocale(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"locale": locale,
+		"enums":  Localize(h.enums, locale),
+	})
+}
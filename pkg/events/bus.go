@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
 )
 
 // HandlerFunc is a function that handles events
@@ -32,6 +34,9 @@ func (b *Bus) Publish(ctx context.Context, eventType string, payload interface{}
 		Timestamp: time.Now(),
 		Context:   ctx,
 	}
+	if authCtx, err := auth.FromContext(ctx); err == nil {
+		event.Sandbox = authCtx.IsSandbox
+	}
 
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -88,3 +91,24 @@ func TestEventBusMultipleHandlers(t *testing.T) {
 	assert.True(t, handler1Called)
 	assert.True(t, handler2Called)
 }
+
+func TestEventBusMarksSandboxEvents(t *testing.T) {
+	bus := NewBus(false)
+
+	var received Event
+	bus.Subscribe("sandbox.event", func(ctx context.Context, event Event) error {
+		received = event
+		return nil
+	})
+
+	ctx := auth.WithAuthContext(context.Background(), &auth.AuthContext{
+		UserID:         uuid.New(),
+		OrganizationID: uuid.New(),
+		IsSandbox:      true,
+	})
+	assert.NoError(t, bus.Publish(ctx, "sandbox.event", nil))
+	assert.True(t, received.Sandbox)
+
+	assert.NoError(t, bus.Publish(context.Background(), "sandbox.event", nil))
+	assert.False(t, received.Sandbox)
+}
@@ -12,4 +12,7 @@ type Event struct {
 	Source    string
 	Timestamp time.Time
 	Context   context.Context
+	// Sandbox marks events raised inside a sandbox organization. Anything that
+	// forwards events outside the process (webhooks, integrations) must carry it.
+	Sandbox bool
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
 	"github.com/google/uuid"
 )
 
@@ -26,6 +28,7 @@ type EventMetadata struct {
 	CorrelationID  *string    `json:"correlation_id,omitempty"`
 	Source         string     `json:"source,omitempty"`
 	Version        string     `json:"version,omitempty"`
+	Sandbox        bool       `json:"sandbox,omitempty"`
 	Timestamp      time.Time  `json:"timestamp"`
 }
 
@@ -72,6 +75,10 @@ func (p *BusEventPublisher) PublishAsync(ctx context.Context, eventType string,
 
 // PublishWithMetadata publishes an event with additional metadata
 func (p *BusEventPublisher) PublishWithMetadata(ctx context.Context, eventType string, payload interface{}, metadata EventMetadata) error {
+	if authCtx, err := auth.FromContext(ctx); err == nil && authCtx.IsSandbox {
+		metadata.Sandbox = true
+	}
+
 	// Enhance the payload with metadata if needed
 	enhancedPayload := map[string]interface{}{
 		"data":     payload,
@@ -0,0 +1,183 @@
+// Package expr evaluates admin-written expressions, such as the logic of
+// custom assignment rules, in a sandbox: an expression can only read the
+// variables it is compiled for and call the functions defined here. It
+// cannot loop, reach the host or run other code.
+//
+// Expressions use the govaluate syntax:
+//
+//	user.is_available && user.active_assignments < params.capacity
+//	lead.priority == "urgent" ? 10 : 1
+//
+// Variables are maps read with dots. A key missing from a map reads as nil.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/casbin/govaluate"
+)
+
+// ErrInvalidExpression is returned for expressions that do not compile
+var ErrInvalidExpression = errors.New("invalid expression")
+
+// MaxLength bounds the source of an expression
+const MaxLength = 4096
+
+// functions are the only functions expressions can call
+var functions = map[string]govaluate.ExpressionFunction{
+	"min": func(args ...interface{}) (interface{}, error) {
+		return fold("min", args, math.Min)
+	},
+	"max": func(args ...interface{}) (interface{}, error) {
+		return fold("max", args, math.Max)
+	},
+	"abs": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("abs takes one number")
+		}
+		n, ok := args[0].(float64)
+		if !ok {
+			return nil, errors.New("abs takes one number")
+		}
+		return math.Abs(n), nil
+	},
+	"lower": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("lower takes one string")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, errors.New("lower takes one string")
+		}
+		return strings.ToLower(s), nil
+	},
+}
+
+func fold(name string, args []interface{}, op func(a, b float64) float64) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s takes at least one number", name)
+	}
+	var result float64
+	for i, arg := range args {
+		n, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s takes numbers", name)
+		}
+		if i == 0 {
+			result = n
+		} else {
+			result = op(result, n)
+		}
+	}
+	return result, nil
+}
+
+// Expression is a compiled expression
+type Expression struct {
+	source string
+	eval   *govaluate.EvaluableExpression
+	// variables are the names the expression may read
+	variables []string
+	// keys are the map keys read from each variable
+	keys map[string][]string
+}
+
+// Compile parses an expression that may read the given variables
+func Compile(source string, variables ...string) (*Expression, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("%w: expression is empty", ErrInvalidExpression)
+	}
+	if len(source) > MaxLength {
+		return nil, fmt.Errorf("%w: expression is longer than %d characters", ErrInvalidExpression, MaxLength)
+	}
+
+	eval, err := govaluate.NewEvaluableExpressionWithFunctions(source, functions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExpression, err)
+	}
+
+	allowed := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		allowed[v] = true
+	}
+	e := &Expression{source: source, eval: eval, variables: variables, keys: map[string][]string{}}
+	for _, token := range eval.Tokens() {
+		switch token.Kind {
+		case govaluate.VARIABLE:
+			name := token.Value.(string)
+			if !allowed[name] {
+				return nil, fmt.Errorf("%w: unknown variable %q, expected one of %s", ErrInvalidExpression, name, strings.Join(sorted(allowed), ", "))
+			}
+		case govaluate.ACCESSOR:
+			path := token.Value.([]string)
+			if !allowed[path[0]] {
+				return nil, fmt.Errorf("%w: unknown variable %q, expected one of %s", ErrInvalidExpression, path[0], strings.Join(sorted(allowed), ", "))
+			}
+			e.keys[path[0]] = append(e.keys[path[0]], path[1])
+		}
+	}
+	return e, nil
+}
+
+func sorted(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the expression's source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression over the variables, which must hold plain
+// data: strings, numbers, booleans, nil, and maps and slices of them
+func (e *Expression) Evaluate(variables map[string]interface{}) (interface{}, error) {
+	// Missing variables and keys read as nil rather than failing the
+	// expression
+	params := make(map[string]interface{}, len(e.variables))
+	for _, name := range e.variables {
+		params[name] = variables[name]
+	}
+	for name, keys := range e.keys {
+		values, ok := params[name].(map[string]interface{})
+		if !ok {
+			if params[name] != nil {
+				continue
+			}
+			values = map[string]interface{}{}
+		}
+		var filled map[string]interface{}
+		for _, key := range keys {
+			if _, ok := values[key]; ok {
+				continue
+			}
+			if filled == nil {
+				filled = make(map[string]interface{}, len(values)+len(keys))
+				for k, v := range values {
+					filled[k] = v
+				}
+			}
+			filled[key] = nil
+		}
+		if filled != nil {
+			params[name] = filled
+		} else {
+			params[name] = values
+		}
+	}
+
+	result, err := e.eval.Evaluate(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %q: %w", e.source, err)
+	}
+	return result, nil
+}
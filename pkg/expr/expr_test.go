@@ -0,0 +1,46 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileOnlyReadsGivenVariables(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"user.active_assignments <",
+		"os.Exit(1)",
+		"secret > 1",
+		"exec(\"rm\")",
+	} {
+		_, err := Compile(source, "lead", "user")
+		assert.ErrorIs(t, err, ErrInvalidExpression, source)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	e, err := Compile(`lead.priority == "urgent" && user.is_available ? max(10 - user.active_assignments, 1) : 0`, "lead", "user")
+	require.NoError(t, err)
+
+	result, err := e.Evaluate(map[string]interface{}{
+		"lead": map[string]interface{}{"priority": "urgent"},
+		"user": map[string]interface{}{"is_available": true, "active_assignments": 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, result)
+
+	// Missing keys and variables read as nil
+	result, err = e.Evaluate(map[string]interface{}{
+		"user": map[string]interface{}{"is_available": true, "active_assignments": 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result)
+
+	e, err = Compile(`lower(lead.country) == "us"`, "lead")
+	require.NoError(t, err)
+	result, err = e.Evaluate(map[string]interface{}{"lead": map[string]interface{}{"country": "US"}})
+	require.NoError(t, err)
+	assert.Equal(t, true, result)
+}
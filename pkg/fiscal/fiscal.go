@@ -0,0 +1,331 @@
+// Package fiscal defines organizations' fiscal years and resolves the
+// fiscal periods analytics are asked for and bucketed by.
+package fiscal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPeriod is returned for fiscal calendars and period parameters
+// that fail validation
+var ErrInvalidPeriod = errors.New("invalid fiscal period")
+
+// Pattern is how a fiscal year splits into its twelve periods
+type Pattern string
+
+const (
+	// PatternMonthly periods are calendar months
+	PatternMonthly Pattern = "monthly"
+	// Week patterns split each quarter into periods of whole weeks. The
+	// year ends on the same weekday each year, so it has 52 weeks, or 53
+	// every five or six years; the extra week goes to the last period.
+	Pattern445 Pattern = "4-4-5"
+	Pattern454 Pattern = "4-5-4"
+	Pattern544 Pattern = "5-4-4"
+)
+
+// weeks returns the weeks of each period of a quarter, nil for monthly
+// periods
+func (p Pattern) weeks() []int {
+	switch p {
+	case Pattern445:
+		return []int{4, 4, 5}
+	case Pattern454:
+		return []int{4, 5, 4}
+	case Pattern544:
+		return []int{5, 4, 4}
+	}
+	return nil
+}
+
+// YearEnd is where a week-based fiscal year ends relative to the end of
+// its last month
+type YearEnd string
+
+const (
+	// YearEndLast years end on the last week-end day of the month
+	YearEndLast YearEnd = "last"
+	// YearEndNearest years end on the week-end day nearest the end of the
+	// month, which may fall in the next month
+	YearEndNearest YearEnd = "nearest"
+)
+
+// Weekday is a day of the week, encoded in JSON by its lowercase name
+type Weekday time.Weekday
+
+func (d Weekday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(time.Weekday(d).String()))
+}
+
+func (d *Weekday) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for w := time.Sunday; w <= time.Saturday; w++ {
+		if strings.EqualFold(w.String(), name) {
+			*d = Weekday(w)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid weekday %q", name)
+}
+
+// Calendar is an organization's fiscal year definition. Fiscal years are
+// named after the calendar year they end in: with a July start, FY2025 runs
+// from July 2024 through June 2025.
+type Calendar struct {
+	StartMonth time.Month `json:"start_month"`
+	Pattern    Pattern    `json:"pattern"`
+	// WeekEnd and YearEnd place the end of week-based years
+	WeekEnd Weekday `json:"week_end"`
+	YearEnd YearEnd `json:"year_end"`
+}
+
+// Default is the calendar of organizations without one: fiscal years are
+// calendar years and periods calendar months
+func Default() *Calendar {
+	return &Calendar{StartMonth: time.January, Pattern: PatternMonthly, WeekEnd: Weekday(time.Saturday), YearEnd: YearEndLast}
+}
+
+// Validate checks the calendar, filling in the defaults of unset fields
+func (c *Calendar) Validate() error {
+	if c.StartMonth == 0 {
+		c.StartMonth = time.January
+	}
+	if c.Pattern == "" {
+		c.Pattern = PatternMonthly
+	}
+	if c.YearEnd == "" {
+		c.YearEnd = YearEndLast
+	}
+	if c.StartMonth < time.January || c.StartMonth > time.December {
+		return fmt.Errorf("%w: start_month must be between 1 and 12", ErrInvalidPeriod)
+	}
+	if c.Pattern != PatternMonthly && c.Pattern.weeks() == nil {
+		return fmt.Errorf("%w: pattern must be monthly, 4-4-5, 4-5-4 or 5-4-4", ErrInvalidPeriod)
+	}
+	if c.WeekEnd < Weekday(time.Sunday) || c.WeekEnd > Weekday(time.Saturday) {
+		return fmt.Errorf("%w: invalid week_end", ErrInvalidPeriod)
+	}
+	if c.YearEnd != YearEndLast && c.YearEnd != YearEndNearest {
+		return fmt.Errorf("%w: year_end must be last or nearest", ErrInvalidPeriod)
+	}
+	return nil
+}
+
+// Kind is the length of a fiscal period
+type Kind string
+
+const (
+	KindYear    Kind = "year"
+	KindQuarter Kind = "quarter"
+	// KindPeriod is one of the twelve periods of a year, its fiscal months
+	KindPeriod Kind = "period"
+)
+
+// Period is a fiscal year, quarter or period. Start and End are dates at
+// midnight UTC; End is the last day of the period.
+type Period struct {
+	Kind       Kind      `json:"kind"`
+	FiscalYear int       `json:"fiscal_year"`
+	Quarter    int       `json:"quarter,omitempty"`
+	Number     int       `json:"number,omitempty"`
+	Label      string    `json:"label"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// Contains reports whether t falls on a day of the period
+func (p Period) Contains(t time.Time) bool {
+	d := dateOf(t)
+	return !d.Before(p.Start) && !d.After(p.End)
+}
+
+// Year returns a fiscal year
+func (c *Calendar) Year(fy int) Period {
+	return Period{
+		Kind:       KindYear,
+		FiscalYear: fy,
+		Label:      fmt.Sprintf("FY%d", fy),
+		Start:      c.yearStart(fy),
+		End:        c.yearEnd(fy),
+	}
+}
+
+// Quarter returns quarter q, 1 to 4, of a fiscal year
+func (c *Calendar) Quarter(fy, q int) Period {
+	first, last := c.Period(fy, 3*q-2), c.Period(fy, 3*q)
+	return Period{
+		Kind:       KindQuarter,
+		FiscalYear: fy,
+		Quarter:    q,
+		Label:      fmt.Sprintf("FY%d-Q%d", fy, q),
+		Start:      first.Start,
+		End:        last.End,
+	}
+}
+
+// Period returns period n, 1 to 12, of a fiscal year
+func (c *Calendar) Period(fy, n int) Period {
+	p := Period{
+		Kind:       KindPeriod,
+		FiscalYear: fy,
+		Quarter:    (n-1)/3 + 1,
+		Number:     n,
+		Label:      fmt.Sprintf("FY%d-P%02d", fy, n),
+	}
+
+	weeks := c.Pattern.weeks()
+	if weeks == nil {
+		p.Start = time.Date(c.startYear(fy), c.StartMonth+time.Month(n-1), 1, 0, 0, 0, 0, time.UTC)
+		p.End = p.Start.AddDate(0, 1, -1)
+		return p
+	}
+
+	before := 0
+	for i := 1; i < n; i++ {
+		before += weeks[(i-1)%3]
+	}
+	p.Start = c.yearStart(fy).AddDate(0, 0, 7*before)
+	p.End = p.Start.AddDate(0, 0, 7*weeks[(n-1)%3]-1)
+	if n == 12 {
+		// The 53rd week of long years
+		p.End = c.yearEnd(fy)
+	}
+	return p
+}
+
+// Periods returns the twelve periods of a fiscal year
+func (c *Calendar) Periods(fy int) []Period {
+	periods := make([]Period, 12)
+	for n := range periods {
+		periods[n] = c.Period(fy, n+1)
+	}
+	return periods
+}
+
+// FiscalYearOf returns the fiscal year t falls in
+func (c *Calendar) FiscalYearOf(t time.Time) int {
+	d := dateOf(t)
+	fy := d.Year()
+	if c.StartMonth != time.January && d.Month() >= c.StartMonth {
+		fy++
+	}
+	if c.Pattern.weeks() != nil {
+		// Week-based years start and end up to a week away from the month
+		if d.After(c.yearEnd(fy)) {
+			fy++
+		} else if d.Before(c.yearStart(fy)) {
+			fy--
+		}
+	}
+	return fy
+}
+
+// PeriodOf returns the period t falls in
+func (c *Calendar) PeriodOf(t time.Time) Period {
+	fy := c.FiscalYearOf(t)
+	for n := 1; n < 12; n++ {
+		if p := c.Period(fy, n); p.Contains(t) {
+			return p
+		}
+	}
+	return c.Period(fy, 12)
+}
+
+// Buckets returns the periods overlapping from through to, in order
+func (c *Calendar) Buckets(from, to time.Time) []Period {
+	to = dateOf(to)
+	var buckets []Period
+	for p := c.PeriodOf(from); !p.Start.After(to); p = c.next(p) {
+		buckets = append(buckets, p)
+	}
+	return buckets
+}
+
+func (c *Calendar) next(p Period) Period {
+	if p.Number == 12 {
+		return c.Period(p.FiscalYear+1, 1)
+	}
+	return c.Period(p.FiscalYear, p.Number+1)
+}
+
+var (
+	absolutePeriod = regexp.MustCompile(`^FY(\d{4})(?:-(?:Q([1-4])|P(0[1-9]|1[0-2])))?$`)
+	relativePeriod = regexp.MustCompile(`^(previous|current|next)-(year|quarter|period)$`)
+)
+
+// Parse resolves a period parameter: FY2025, FY2025-Q2 or FY2025-P07, or
+// relative to today, current-quarter, previous-year, next-period and the
+// like
+func (c *Calendar) Parse(spec string, today time.Time) (Period, error) {
+	spec = strings.TrimSpace(spec)
+	if m := absolutePeriod.FindStringSubmatch(strings.ToUpper(spec)); m != nil {
+		fy, _ := strconv.Atoi(m[1])
+		switch {
+		case m[2] != "":
+			q, _ := strconv.Atoi(m[2])
+			return c.Quarter(fy, q), nil
+		case m[3] != "":
+			n, _ := strconv.Atoi(m[3])
+			return c.Period(fy, n), nil
+		}
+		return c.Year(fy), nil
+	}
+
+	m := relativePeriod.FindStringSubmatch(strings.ToLower(spec))
+	if m == nil {
+		return Period{}, fmt.Errorf("%w: %q is not FY2025, FY2025-Q2, FY2025-P07 or current-quarter", ErrInvalidPeriod, spec)
+	}
+	shift := map[string]int{"previous": -1, "current": 0, "next": 1}[m[1]]
+	current := c.PeriodOf(today)
+	switch Kind(m[2]) {
+	case KindYear:
+		return c.Year(current.FiscalYear + shift), nil
+	case KindQuarter:
+		q := current.FiscalYear*4 + current.Quarter - 1 + shift
+		return c.Quarter(q/4, q%4+1), nil
+	}
+	n := current.FiscalYear*12 + current.Number - 1 + shift
+	return c.Period(n/12, n%12+1), nil
+}
+
+// startYear is the calendar year a fiscal year's first month falls in
+func (c *Calendar) startYear(fy int) int {
+	if c.StartMonth == time.January {
+		return fy
+	}
+	return fy - 1
+}
+
+func (c *Calendar) yearStart(fy int) time.Time {
+	if c.Pattern.weeks() == nil {
+		return time.Date(c.startYear(fy), c.StartMonth, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return c.yearEnd(fy-1).AddDate(0, 0, 1)
+}
+
+func (c *Calendar) yearEnd(fy int) time.Time {
+	// The last day of the month before the start month, in the year the
+	// fiscal year is named after
+	monthEnd := time.Date(c.startYear(fy)+1, c.StartMonth, 0, 0, 0, 0, 0, time.UTC)
+	if c.Pattern.weeks() == nil {
+		return monthEnd
+	}
+	last := monthEnd.AddDate(0, 0, -((int(monthEnd.Weekday()) - int(c.WeekEnd) + 7) % 7))
+	if c.YearEnd == YearEndNearest && monthEnd.Sub(last) > 3*24*time.Hour {
+		return last.AddDate(0, 0, 7)
+	}
+	return last
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,113 @@
+package fiscal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMonthlyCalendarStartingInJuly(t *testing.T) {
+	cal := &Calendar{StartMonth: time.July}
+	require.NoError(t, cal.Validate())
+
+	year := cal.Year(2025)
+	assert.Equal(t, date(2024, 7, 1), year.Start)
+	assert.Equal(t, date(2025, 6, 30), year.End)
+
+	q2 := cal.Quarter(2025, 2)
+	assert.Equal(t, "FY2025-Q2", q2.Label)
+	assert.Equal(t, date(2024, 10, 1), q2.Start)
+	assert.Equal(t, date(2024, 12, 31), q2.End)
+
+	p := cal.PeriodOf(time.Date(2025, 2, 14, 18, 0, 0, 0, time.UTC))
+	assert.Equal(t, "FY2025-P08", p.Label)
+	assert.Equal(t, date(2025, 2, 28), p.End)
+	assert.Equal(t, 2026, cal.FiscalYearOf(date(2025, 7, 1)))
+}
+
+func TestWeekCalendar(t *testing.T) {
+	// Years end on the last Saturday of December
+	cal := &Calendar{Pattern: Pattern445, WeekEnd: Weekday(time.Saturday)}
+	require.NoError(t, cal.Validate())
+
+	year := cal.Year(2025)
+	assert.Equal(t, date(2024, 12, 29), year.Start)
+	assert.Equal(t, date(2025, 12, 27), year.End)
+
+	periods := cal.Periods(2025)
+	assert.Equal(t, date(2025, 1, 25), periods[0].End, "4 weeks")
+	assert.Equal(t, date(2025, 2, 22), periods[1].End, "4 weeks")
+	assert.Equal(t, date(2025, 3, 29), periods[2].End, "5 weeks")
+	for i := 1; i < len(periods); i++ {
+		assert.Equal(t, periods[i-1].End.AddDate(0, 0, 1), periods[i].Start)
+	}
+
+	// 2021 ended on 2021-12-25 and 2022 on 2022-12-31, so 2022 has 53
+	// weeks, the last in P12
+	long := cal.Year(2022)
+	assert.Equal(t, 53*7, int(long.End.Sub(long.Start).Hours()/24)+1)
+	assert.Equal(t, 6*7, int(cal.Period(2022, 12).End.Sub(cal.Period(2022, 12).Start).Hours()/24)+1)
+
+	// A date between the end of December and the next year's start
+	assert.Equal(t, 2026, cal.FiscalYearOf(date(2025, 12, 30)))
+
+	nearest := &Calendar{Pattern: Pattern445, WeekEnd: Weekday(time.Saturday), YearEnd: YearEndNearest}
+	require.NoError(t, nearest.Validate())
+	assert.Equal(t, date(2026, 1, 3), nearest.Year(2025).End, "Saturday nearest Wednesday December 31")
+}
+
+func TestParse(t *testing.T) {
+	cal := &Calendar{StartMonth: time.April}
+	require.NoError(t, cal.Validate())
+	today := date(2025, 5, 20)
+
+	for spec, label := range map[string]string{
+		"FY2026":           "FY2026",
+		"fy2026-q3":        "FY2026-Q3",
+		"FY2026-P12":       "FY2026-P12",
+		"current-year":     "FY2026",
+		"current-quarter":  "FY2026-Q1",
+		"previous-quarter": "FY2025-Q4",
+		"next-period":      "FY2026-P03",
+		"previous-period":  "FY2026-P01",
+	} {
+		p, err := cal.Parse(spec, today)
+		require.NoError(t, err, spec)
+		assert.Equal(t, label, p.Label, spec)
+	}
+
+	for _, spec := range []string{"", "2025", "FY2025-Q5", "FY2025-P13", "last-year"} {
+		_, err := cal.Parse(spec, today)
+		assert.ErrorIs(t, err, ErrInvalidPeriod, spec)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	cal := Default()
+	buckets := cal.Buckets(date(2025, 11, 15), date(2026, 1, 1))
+	require.Len(t, buckets, 3)
+	assert.Equal(t, []string{"FY2025-P11", "FY2025-P12", "FY2026-P01"},
+		[]string{buckets[0].Label, buckets[1].Label, buckets[2].Label})
+}
+
+func TestCalendarJSON(t *testing.T) {
+	var cal Calendar
+	require.NoError(t, json.Unmarshal([]byte(`{"start_month":10,"pattern":"5-4-4","week_end":"friday"}`), &cal))
+	require.NoError(t, cal.Validate())
+	assert.Equal(t, Weekday(time.Friday), cal.WeekEnd)
+	assert.Equal(t, YearEndLast, cal.YearEnd)
+
+	encoded, err := json.Marshal(cal)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"start_month":10,"pattern":"5-4-4","week_end":"friday","year_end":"last"}`, string(encoded))
+
+	bad := Calendar{StartMonth: 13}
+	assert.ErrorIs(t, bad.Validate(), ErrInvalidPeriod)
+}
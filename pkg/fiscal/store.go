@@ -0,0 +1,103 @@
+package fiscal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store loads and saves the fiscal calendar of each organization
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a fiscal calendar store
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Find returns the organization's fiscal calendar, the Default one when
+// it has not configured any
+func (s *Store) Find(ctx context.Context, orgID uuid.UUID) (*Calendar, error) {
+	var cal Calendar
+	var weekEnd int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT start_month, pattern, week_end, year_end
+		FROM fiscal_calendars
+		WHERE organization_id = $1
+	`, orgID).Scan(&cal.StartMonth, &cal.Pattern, &weekEnd, &cal.YearEnd)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fiscal calendar: %w", err)
+	}
+	cal.WeekEnd = Weekday(weekEnd)
+	return &cal, nil
+}
+
+// Save creates or replaces the organization's fiscal calendar
+func (s *Store) Save(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, cal Calendar) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO fiscal_calendars (organization_id, start_month, pattern, week_end, year_end, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (organization_id) DO UPDATE SET
+			start_month = EXCLUDED.start_month,
+			pattern = EXCLUDED.pattern,
+			week_end = EXCLUDED.week_end,
+			year_end = EXCLUDED.year_end,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, orgID, int(cal.StartMonth), cal.Pattern, int(cal.WeekEnd), cal.YearEnd, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save fiscal calendar: %w", err)
+	}
+	return nil
+}
+
+// CalendarSource loads an organization's fiscal calendar
+type CalendarSource interface {
+	Find(ctx context.Context, orgID uuid.UUID) (*Calendar, error)
+}
+
+// Periods resolves the fiscal period parameters of analytics in the
+// organization's fiscal calendar, so that every report reads FY2025-Q2 the
+// same way
+type Periods struct {
+	source CalendarSource
+	now    func() time.Time
+}
+
+// NewPeriods creates a period resolver over a calendar source
+func NewPeriods(source CalendarSource) *Periods {
+	return &Periods{source: source, now: time.Now}
+}
+
+// Calendar returns the organization's fiscal calendar
+func (p *Periods) Calendar(ctx context.Context, orgID uuid.UUID) (*Calendar, error) {
+	return p.source.Find(ctx, orgID)
+}
+
+// Resolve returns the period a parameter names, relative ones taken from
+// today in UTC
+func (p *Periods) Resolve(ctx context.Context, orgID uuid.UUID, spec string) (Period, error) {
+	cal, err := p.source.Find(ctx, orgID)
+	if err != nil {
+		return Period{}, err
+	}
+	return cal.Parse(spec, p.now().UTC())
+}
+
+// Buckets returns the organization's fiscal periods overlapping from
+// through to, in order
+func (p *Periods) Buckets(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]Period, error) {
+	cal, err := p.source.Find(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return cal.Buckets(from, to), nil
+}
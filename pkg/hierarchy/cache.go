@@ -0,0 +1,113 @@
+package hierarchy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCacheTTL bounds how stale a cached hierarchy lookup may be. HR changes
+// are infrequent, and callers that edit the structure can call Invalidate.
+const DefaultCacheTTL = 5 * time.Minute
+
+type cacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// CachedDirectory memoizes Directory lookups per organization for a fixed TTL
+type CachedDirectory struct {
+	next Directory
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]map[string]cacheEntry
+}
+
+// NewCachedDirectory wraps a directory with a TTL cache. A non-positive ttl uses DefaultCacheTTL.
+func NewCachedDirectory(next Directory, ttl time.Duration) *CachedDirectory {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedDirectory{
+		next:    next,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]map[string]cacheEntry),
+	}
+}
+
+// Invalidate drops every cached lookup for the organization
+func (c *CachedDirectory) Invalidate(orgID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, orgID)
+}
+
+func (c *CachedDirectory) get(orgID uuid.UUID, key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[orgID][key]
+	if !ok || c.now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *CachedDirectory) put(orgID uuid.UUID, key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	org, ok := c.entries[orgID]
+	if !ok {
+		org = make(map[string]cacheEntry)
+		c.entries[orgID] = org
+	}
+	org[key] = cacheEntry{value: value, expiresAt: c.now().Add(c.ttl)}
+}
+
+// cached runs load on a miss and stores the result, including nil results,
+// so absent records are not looked up again until the entry expires
+func cached[T any](c *CachedDirectory, orgID uuid.UUID, key string, load func() (T, error)) (T, error) {
+	if v, ok := c.get(orgID, key); ok {
+		return v.(T), nil
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	c.put(orgID, key, v)
+	return v, nil
+}
+
+func (c *CachedDirectory) EmployeeByUser(ctx context.Context, orgID, userID uuid.UUID) (*Employee, error) {
+	return cached(c, orgID, "user:"+userID.String(), func() (*Employee, error) {
+		return c.next.EmployeeByUser(ctx, orgID, userID)
+	})
+}
+
+func (c *CachedDirectory) Employee(ctx context.Context, orgID, employeeID uuid.UUID) (*Employee, error) {
+	return cached(c, orgID, "employee:"+employeeID.String(), func() (*Employee, error) {
+		return c.next.Employee(ctx, orgID, employeeID)
+	})
+}
+
+func (c *CachedDirectory) Department(ctx context.Context, orgID, departmentID uuid.UUID) (*Department, error) {
+	return cached(c, orgID, "department:"+departmentID.String(), func() (*Department, error) {
+		return c.next.Department(ctx, orgID, departmentID)
+	})
+}
+
+func (c *CachedDirectory) TeamLeader(ctx context.Context, orgID, userID uuid.UUID) (*uuid.UUID, error) {
+	return cached(c, orgID, "team_leader:"+userID.String(), func() (*uuid.UUID, error) {
+		return c.next.TeamLeader(ctx, orgID, userID)
+	})
+}
+
+func (c *CachedDirectory) MembersWithRole(ctx context.Context, orgID uuid.UUID, role string) ([]uuid.UUID, error) {
+	return cached(c, orgID, "role:"+role, func() ([]uuid.UUID, error) {
+		return c.next.MembersWithRole(ctx, orgID, role)
+	})
+}
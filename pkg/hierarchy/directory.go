@@ -0,0 +1,156 @@
+package hierarchy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Employee is the slice of an HR employee record used for hierarchy resolution
+type Employee struct {
+	ID           uuid.UUID
+	UserID       *uuid.UUID
+	Name         string
+	ManagerID    *uuid.UUID
+	DepartmentID *uuid.UUID
+}
+
+// Department is the slice of an HR department record used for hierarchy resolution
+type Department struct {
+	ID        uuid.UUID
+	ParentID  *uuid.UUID
+	ManagerID *uuid.UUID
+}
+
+// Directory looks up the organization structure. Lookups return nil without
+// an error when the record does not exist.
+type Directory interface {
+	EmployeeByUser(ctx context.Context, orgID, userID uuid.UUID) (*Employee, error)
+	Employee(ctx context.Context, orgID, employeeID uuid.UUID) (*Employee, error)
+	Department(ctx context.Context, orgID, departmentID uuid.UUID) (*Department, error)
+	TeamLeader(ctx context.Context, orgID, userID uuid.UUID) (*uuid.UUID, error)
+	MembersWithRole(ctx context.Context, orgID uuid.UUID, role string) ([]uuid.UUID, error)
+}
+
+// SQLDirectory reads the organization structure from the HR and CRM tables
+type SQLDirectory struct {
+	db *sql.DB
+}
+
+// NewSQLDirectory creates a directory backed by the database
+func NewSQLDirectory(db *sql.DB) *SQLDirectory {
+	return &SQLDirectory{db: db}
+}
+
+const employeeColumns = `id, user_id, name, parent_id, department_id`
+
+func (d *SQLDirectory) EmployeeByUser(ctx context.Context, orgID, userID uuid.UUID) (*Employee, error) {
+	query := `
+		SELECT ` + employeeColumns + `
+		FROM employees
+		WHERE organization_id = $1 AND user_id = $2 AND active = true AND deleted_at IS NULL
+		ORDER BY created_at
+		LIMIT 1
+	`
+	return d.scanEmployee(d.db.QueryRowContext(ctx, query, orgID, userID))
+}
+
+func (d *SQLDirectory) Employee(ctx context.Context, orgID, employeeID uuid.UUID) (*Employee, error) {
+	query := `
+		SELECT ` + employeeColumns + `
+		FROM employees
+		WHERE organization_id = $1 AND id = $2 AND active = true AND deleted_at IS NULL
+	`
+	return d.scanEmployee(d.db.QueryRowContext(ctx, query, orgID, employeeID))
+}
+
+func (d *SQLDirectory) scanEmployee(row *sql.Row) (*Employee, error) {
+	var e Employee
+	if err := row.Scan(&e.ID, &e.UserID, &e.Name, &e.ManagerID, &e.DepartmentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load employee: %w", err)
+	}
+	return &e, nil
+}
+
+func (d *SQLDirectory) Department(ctx context.Context, orgID, departmentID uuid.UUID) (*Department, error) {
+	query := `
+		SELECT id, parent_id, manager_id
+		FROM departments
+		WHERE organization_id = $1 AND id = $2 AND active = true
+	`
+
+	var dept Department
+	err := d.db.QueryRowContext(ctx, query, orgID, departmentID).Scan(&dept.ID, &dept.ParentID, &dept.ManagerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load department: %w", err)
+	}
+	return &dept, nil
+}
+
+// TeamLeader returns the leader of the oldest active sales team the user is a
+// member of, excluding teams the user leads
+func (d *SQLDirectory) TeamLeader(ctx context.Context, orgID, userID uuid.UUID) (*uuid.UUID, error) {
+	query := `
+		SELECT team_leader_id
+		FROM sales_teams
+		WHERE organization_id = $1
+			AND is_active = true
+			AND deleted_at IS NULL
+			AND team_leader_id IS NOT NULL
+			AND team_leader_id <> $2
+			AND $2 = ANY(member_ids)
+		ORDER BY created_at
+		LIMIT 1
+	`
+
+	var leaderID uuid.UUID
+	if err := d.db.QueryRowContext(ctx, query, orgID, userID).Scan(&leaderID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load team leader: %w", err)
+	}
+	return &leaderID, nil
+}
+
+// maxRoleMembers bounds how many members of a role are considered as fallbacks
+const maxRoleMembers = 20
+
+// MembersWithRole returns active organization members with the role, longest-standing first
+func (d *SQLDirectory) MembersWithRole(ctx context.Context, orgID uuid.UUID, role string) ([]uuid.UUID, error) {
+	query := `
+		SELECT user_id
+		FROM organization_users
+		WHERE organization_id = $1 AND role = $2 AND is_active = true
+		ORDER BY joined_at NULLS LAST, created_at
+		LIMIT $3
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, orgID, role, maxRoleMembers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization members: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load organization members: %w", err)
+	}
+	return userIDs, nil
+}
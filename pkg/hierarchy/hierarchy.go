@@ -0,0 +1,270 @@
+// Package hierarchy resolves who is above a user in the organization, based on
+// the HR employee and department structure, and applies escalation policies
+// with fallback chains on top of it.
+package hierarchy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// maxDepth bounds walks up the management and department trees so that
+// cycles in HR data cannot loop forever
+const maxDepth = 32
+
+// Reasons a fallback target was skipped
+const (
+	ReasonNotFound         = "not_found"
+	ReasonNoUserAccount    = "no_user_account"
+	ReasonSelf             = "self"
+	ReasonAlreadyEscalated = "already_escalated"
+)
+
+// Person is someone in the hierarchy. UserID is nil for employees without a
+// user account, who cannot receive escalations.
+type Person struct {
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	EmployeeID *uuid.UUID `json:"employee_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+}
+
+// Attempt records a fallback target that did not produce a recipient
+type Attempt struct {
+	Target Target `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// Escalation is the outcome of one policy level. Recipient and Matched are nil
+// when every target in the level's fallback chain was skipped.
+type Escalation struct {
+	Level     int       `json:"level"`
+	Recipient *Person   `json:"recipient,omitempty"`
+	Matched   *Target   `json:"matched,omitempty"`
+	Skipped   []Attempt `json:"skipped,omitempty"`
+}
+
+// Resolver answers hierarchy questions against a Directory
+type Resolver struct {
+	dir Directory
+}
+
+// NewResolver creates a resolver. Wrap the directory in a CachedDirectory when
+// the resolver serves request traffic.
+func NewResolver(dir Directory) *Resolver {
+	return &Resolver{dir: dir}
+}
+
+// Manager returns the user's direct manager, or nil if the user has none
+func (r *Resolver) Manager(ctx context.Context, orgID, userID uuid.UUID) (*Person, error) {
+	emp, err := r.dir.EmployeeByUser(ctx, orgID, userID)
+	if err != nil || emp == nil {
+		return nil, err
+	}
+	mgr, err := r.manager(ctx, orgID, emp)
+	if err != nil || mgr == nil {
+		return nil, err
+	}
+	return personFromEmployee(mgr), nil
+}
+
+// Chain returns the user's management chain, starting with the direct manager
+func (r *Resolver) Chain(ctx context.Context, orgID, userID uuid.UUID) ([]Person, error) {
+	emp, err := r.dir.EmployeeByUser(ctx, orgID, userID)
+	if err != nil || emp == nil {
+		return nil, err
+	}
+
+	var chain []Person
+	seen := map[uuid.UUID]bool{emp.ID: true}
+	for depth := 0; depth < maxDepth; depth++ {
+		mgr, err := r.manager(ctx, orgID, emp)
+		if err != nil {
+			return nil, err
+		}
+		if mgr == nil || seen[mgr.ID] {
+			break
+		}
+		seen[mgr.ID] = true
+		chain = append(chain, *personFromEmployee(mgr))
+		emp = mgr
+	}
+	return chain, nil
+}
+
+// Escalate resolves every level of the policy for the subject user. Within a
+// level the first target that yields a user other than the subject, and not
+// already chosen by an earlier level, becomes the recipient.
+func (r *Resolver) Escalate(ctx context.Context, orgID, subjectID uuid.UUID, policy Policy) ([]Escalation, error) {
+	used := map[uuid.UUID]bool{subjectID: true}
+	results := make([]Escalation, 0, len(policy.Levels))
+
+	for i, level := range policy.Levels {
+		result := Escalation{Level: i + 1}
+		for _, target := range level.Targets {
+			candidates, err := r.resolve(ctx, orgID, subjectID, target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s target: %w", target.Kind, err)
+			}
+
+			recipient, reason := pick(candidates, subjectID, used)
+			if recipient == nil {
+				result.Skipped = append(result.Skipped, Attempt{Target: target, Reason: reason})
+				continue
+			}
+
+			matched := target
+			result.Recipient = recipient
+			result.Matched = &matched
+			used[*recipient.UserID] = true
+			break
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// pick returns the first eligible candidate, or the reason the first candidate was rejected
+func pick(candidates []Person, subjectID uuid.UUID, used map[uuid.UUID]bool) (*Person, string) {
+	reason := ReasonNotFound
+	for i, c := range candidates {
+		var rejected string
+		switch {
+		case c.UserID == nil:
+			rejected = ReasonNoUserAccount
+		case *c.UserID == subjectID:
+			rejected = ReasonSelf
+		case used[*c.UserID]:
+			rejected = ReasonAlreadyEscalated
+		default:
+			return &candidates[i], ""
+		}
+		if i == 0 {
+			reason = rejected
+		}
+	}
+	return nil, reason
+}
+
+// resolve returns the people a target points at, in preference order
+func (r *Resolver) resolve(ctx context.Context, orgID, subjectID uuid.UUID, target Target) ([]Person, error) {
+	switch target.Kind {
+	case TargetManager, TargetSkipLevelManager, TargetDepartmentManager:
+		emp, err := r.dir.EmployeeByUser(ctx, orgID, subjectID)
+		if err != nil || emp == nil {
+			return nil, err
+		}
+
+		var found *Employee
+		switch target.Kind {
+		case TargetManager:
+			found, err = r.manager(ctx, orgID, emp)
+		case TargetSkipLevelManager:
+			found, err = r.manager(ctx, orgID, emp)
+			if err == nil && found != nil {
+				found, err = r.manager(ctx, orgID, found)
+			}
+		case TargetDepartmentManager:
+			found, err = r.departmentManager(ctx, orgID, emp)
+		}
+		if err != nil || found == nil {
+			return nil, err
+		}
+		return []Person{*personFromEmployee(found)}, nil
+
+	case TargetTeamLeader:
+		leaderID, err := r.dir.TeamLeader(ctx, orgID, subjectID)
+		if err != nil || leaderID == nil {
+			return nil, err
+		}
+		p, err := r.personForUser(ctx, orgID, *leaderID)
+		if err != nil {
+			return nil, err
+		}
+		return []Person{p}, nil
+
+	case TargetOrgRole:
+		userIDs, err := r.dir.MembersWithRole(ctx, orgID, target.Role)
+		if err != nil {
+			return nil, err
+		}
+		people := make([]Person, 0, len(userIDs))
+		for _, id := range userIDs {
+			p, err := r.personForUser(ctx, orgID, id)
+			if err != nil {
+				return nil, err
+			}
+			people = append(people, p)
+		}
+		return people, nil
+
+	case TargetUser:
+		if target.UserID == nil {
+			return nil, nil
+		}
+		p, err := r.personForUser(ctx, orgID, *target.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return []Person{p}, nil
+	}
+	return nil, fmt.Errorf("unknown target kind %q", target.Kind)
+}
+
+func (r *Resolver) manager(ctx context.Context, orgID uuid.UUID, emp *Employee) (*Employee, error) {
+	if emp.ManagerID == nil || *emp.ManagerID == emp.ID {
+		return nil, nil
+	}
+	return r.dir.Employee(ctx, orgID, *emp.ManagerID)
+}
+
+// departmentManager walks up from the employee's department to the first
+// department managed by someone else
+func (r *Resolver) departmentManager(ctx context.Context, orgID uuid.UUID, emp *Employee) (*Employee, error) {
+	deptID := emp.DepartmentID
+	seen := make(map[uuid.UUID]bool)
+	for depth := 0; deptID != nil && depth < maxDepth; depth++ {
+		if seen[*deptID] {
+			return nil, nil
+		}
+		seen[*deptID] = true
+
+		dept, err := r.dir.Department(ctx, orgID, *deptID)
+		if err != nil || dept == nil {
+			return nil, err
+		}
+		if dept.ManagerID != nil && *dept.ManagerID != emp.ID {
+			mgr, err := r.dir.Employee(ctx, orgID, *dept.ManagerID)
+			if err != nil {
+				return nil, err
+			}
+			if mgr != nil {
+				return mgr, nil
+			}
+		}
+		deptID = dept.ParentID
+	}
+	return nil, nil
+}
+
+// personForUser describes a user, adding employee details when the user has an employee record
+func (r *Resolver) personForUser(ctx context.Context, orgID, userID uuid.UUID) (Person, error) {
+	id := userID
+	p := Person{UserID: &id}
+	emp, err := r.dir.EmployeeByUser(ctx, orgID, userID)
+	if err != nil {
+		return p, err
+	}
+	if emp != nil {
+		empID := emp.ID
+		p.EmployeeID = &empID
+		p.Name = emp.Name
+	}
+	return p, nil
+}
+
+func personFromEmployee(emp *Employee) *Person {
+	empID := emp.ID
+	return &Person{UserID: emp.UserID, EmployeeID: &empID, Name: emp.Name}
+}
@@ -0,0 +1,233 @@
+package hierarchy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDirectory struct {
+	employees   map[uuid.UUID]*Employee
+	departments map[uuid.UUID]*Department
+	teamLeaders map[uuid.UUID]uuid.UUID
+	roles       map[string][]uuid.UUID
+	calls       int
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		employees:   make(map[uuid.UUID]*Employee),
+		departments: make(map[uuid.UUID]*Department),
+		teamLeaders: make(map[uuid.UUID]uuid.UUID),
+		roles:       make(map[string][]uuid.UUID),
+	}
+}
+
+// addEmployee adds an employee with a user account reporting to manager (if any)
+func (f *fakeDirectory) addEmployee(name string, manager *Employee, withUser bool) *Employee {
+	emp := &Employee{ID: uuid.New(), Name: name}
+	if withUser {
+		userID := uuid.New()
+		emp.UserID = &userID
+	}
+	if manager != nil {
+		emp.ManagerID = &manager.ID
+	}
+	f.employees[emp.ID] = emp
+	return emp
+}
+
+func (f *fakeDirectory) EmployeeByUser(ctx context.Context, orgID, userID uuid.UUID) (*Employee, error) {
+	f.calls++
+	for _, e := range f.employees {
+		if e.UserID != nil && *e.UserID == userID {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDirectory) Employee(ctx context.Context, orgID, employeeID uuid.UUID) (*Employee, error) {
+	f.calls++
+	return f.employees[employeeID], nil
+}
+
+func (f *fakeDirectory) Department(ctx context.Context, orgID, departmentID uuid.UUID) (*Department, error) {
+	f.calls++
+	return f.departments[departmentID], nil
+}
+
+func (f *fakeDirectory) TeamLeader(ctx context.Context, orgID, userID uuid.UUID) (*uuid.UUID, error) {
+	f.calls++
+	if leader, ok := f.teamLeaders[userID]; ok {
+		return &leader, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeDirectory) MembersWithRole(ctx context.Context, orgID uuid.UUID, role string) ([]uuid.UUID, error) {
+	f.calls++
+	return f.roles[role], nil
+}
+
+func TestChainStopsAtCycles(t *testing.T) {
+	dir := newFakeDirectory()
+	ceo := dir.addEmployee("CEO", nil, true)
+	vp := dir.addEmployee("VP", ceo, false)
+	rep := dir.addEmployee("Rep", vp, true)
+	// Bad HR data: the CEO reports to the rep
+	ceo.ManagerID = &rep.ID
+
+	chain, err := NewResolver(dir).Chain(context.Background(), uuid.New(), *rep.UserID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.Equal(t, "VP", chain[0].Name)
+	assert.Nil(t, chain[0].UserID)
+	assert.Equal(t, "CEO", chain[1].Name)
+}
+
+func TestEscalateFallsBackThroughChain(t *testing.T) {
+	ctx := context.Background()
+	dir := newFakeDirectory()
+	ceo := dir.addEmployee("CEO", nil, true)
+	vp := dir.addEmployee("VP", ceo, false)
+	rep := dir.addEmployee("Rep", vp, true)
+
+	deptHead := dir.addEmployee("Head of Sales", ceo, true)
+	deptID := uuid.New()
+	dir.departments[deptID] = &Department{ID: deptID, ManagerID: &deptHead.ID}
+	rep.DepartmentID = &deptID
+
+	admin := uuid.New()
+	dir.roles["admin"] = []uuid.UUID{*rep.UserID, admin}
+	dir.roles["owner"] = []uuid.UUID{*ceo.UserID}
+
+	escalations, err := NewResolver(dir).Escalate(ctx, uuid.New(), *rep.UserID, DefaultPolicy())
+	require.NoError(t, err)
+	require.Len(t, escalations, 2)
+
+	// The direct manager has no user account, so the department manager is used
+	first := escalations[0]
+	require.NotNil(t, first.Recipient)
+	assert.Equal(t, deptHead.UserID, first.Recipient.UserID)
+	assert.Equal(t, TargetDepartmentManager, first.Matched.Kind)
+	assert.Equal(t, []Attempt{{Target: Target{Kind: TargetManager}, Reason: ReasonNoUserAccount}}, first.Skipped)
+
+	// The skip-level manager is the CEO
+	second := escalations[1]
+	require.NotNil(t, second.Recipient)
+	assert.Equal(t, ceo.UserID, second.Recipient.UserID)
+	assert.Equal(t, TargetSkipLevelManager, second.Matched.Kind)
+	assert.Empty(t, second.Skipped)
+}
+
+func TestEscalateSkipsSubjectAndEarlierRecipients(t *testing.T) {
+	ctx := context.Background()
+	dir := newFakeDirectory()
+	boss := dir.addEmployee("Boss", nil, true)
+	rep := dir.addEmployee("Rep", boss, true)
+
+	admin := uuid.New()
+	dir.roles["admin"] = []uuid.UUID{*rep.UserID, admin}
+	dir.teamLeaders[*rep.UserID] = *boss.UserID
+
+	policy := Policy{Code: "approvals", Levels: []Level{
+		{Targets: []Target{{Kind: TargetManager}}},
+		{Targets: []Target{{Kind: TargetTeamLeader}, {Kind: TargetOrgRole, Role: "admin"}}},
+		{Targets: []Target{{Kind: TargetOrgRole, Role: "owner"}}},
+	}}
+	require.NoError(t, policy.Validate())
+
+	escalations, err := NewResolver(dir).Escalate(ctx, uuid.New(), *rep.UserID, policy)
+	require.NoError(t, err)
+	require.Len(t, escalations, 3)
+
+	assert.Equal(t, boss.UserID, escalations[0].Recipient.UserID)
+
+	// The team leader already received level one; the subject is skipped among admins
+	require.NotNil(t, escalations[1].Recipient)
+	assert.Equal(t, admin, *escalations[1].Recipient.UserID)
+	assert.Equal(t, []Attempt{{Target: Target{Kind: TargetTeamLeader}, Reason: ReasonAlreadyEscalated}}, escalations[1].Skipped)
+
+	// Nobody holds the owner role
+	assert.Nil(t, escalations[2].Recipient)
+	assert.Equal(t, ReasonNotFound, escalations[2].Skipped[0].Reason)
+}
+
+func TestDepartmentManagerWalksUpPastSelf(t *testing.T) {
+	dir := newFakeDirectory()
+	director := dir.addEmployee("Director", nil, true)
+	lead := dir.addEmployee("Lead", nil, true)
+
+	parentID, childID := uuid.New(), uuid.New()
+	dir.departments[parentID] = &Department{ID: parentID, ManagerID: &director.ID}
+	dir.departments[childID] = &Department{ID: childID, ParentID: &parentID, ManagerID: &lead.ID}
+	lead.DepartmentID = &childID
+
+	escalations, err := NewResolver(dir).Escalate(context.Background(), uuid.New(), *lead.UserID, Policy{
+		Code:   "dept",
+		Levels: []Level{{Targets: []Target{{Kind: TargetDepartmentManager}}}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, escalations[0].Recipient)
+	assert.Equal(t, director.UserID, escalations[0].Recipient.UserID)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.NoError(t, DefaultPolicy().Validate())
+
+	cases := map[string]Policy{
+		"bad code":       {Code: "Bad Code", Levels: DefaultPolicy().Levels},
+		"no levels":      {Code: "empty"},
+		"empty level":    {Code: "x", Levels: []Level{{}}},
+		"unknown kind":   {Code: "x", Levels: []Level{{Targets: []Target{{Kind: "ceo"}}}}},
+		"missing role":   {Code: "x", Levels: []Level{{Targets: []Target{{Kind: TargetOrgRole, Role: "viewer"}}}}},
+		"missing userID": {Code: "x", Levels: []Level{{Targets: []Target{{Kind: TargetUser}}}}},
+		"too many levels": {Code: "x", Levels: []Level{
+			{Targets: []Target{{Kind: TargetManager}}}, {Targets: []Target{{Kind: TargetManager}}},
+			{Targets: []Target{{Kind: TargetManager}}}, {Targets: []Target{{Kind: TargetManager}}},
+			{Targets: []Target{{Kind: TargetManager}}}, {Targets: []Target{{Kind: TargetManager}}},
+		}},
+	}
+	for name, policy := range cases {
+		assert.Error(t, policy.Validate(), name)
+	}
+}
+
+func TestCachedDirectoryExpiresAndInvalidates(t *testing.T) {
+	ctx := context.Background()
+	dir := newFakeDirectory()
+	rep := dir.addEmployee("Rep", nil, true)
+	orgID := uuid.New()
+
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	cache := NewCachedDirectory(dir, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		emp, err := cache.EmployeeByUser(ctx, orgID, *rep.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, rep.ID, emp.ID)
+	}
+	assert.Equal(t, 1, dir.calls)
+
+	// Missing records are cached too
+	missing := uuid.New()
+	_, _ = cache.EmployeeByUser(ctx, orgID, missing)
+	emp, err := cache.EmployeeByUser(ctx, orgID, missing)
+	require.NoError(t, err)
+	assert.Nil(t, emp)
+	assert.Equal(t, 2, dir.calls)
+
+	now = now.Add(2 * time.Minute)
+	_, _ = cache.EmployeeByUser(ctx, orgID, *rep.UserID)
+	assert.Equal(t, 3, dir.calls)
+
+	cache.Invalidate(orgID)
+	_, _ = cache.EmployeeByUser(ctx, orgID, *rep.UserID)
+	assert.Equal(t, 4, dir.calls)
+}
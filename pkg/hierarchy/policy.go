@@ -0,0 +1,120 @@
+package hierarchy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// TargetKind identifies how an escalation target is resolved from the subject user
+type TargetKind string
+
+const (
+	// TargetManager is the user's direct manager (employees.parent_id)
+	TargetManager TargetKind = "manager"
+	// TargetSkipLevelManager is the manager of the user's direct manager
+	TargetSkipLevelManager TargetKind = "skip_level_manager"
+	// TargetDepartmentManager is the manager of the user's department, walking
+	// up parent departments until a manager other than the user is found
+	TargetDepartmentManager TargetKind = "department_manager"
+	// TargetTeamLeader is the leader of a sales team the user belongs to
+	TargetTeamLeader TargetKind = "team_leader"
+	// TargetOrgRole is an active organization member with the given role
+	TargetOrgRole TargetKind = "org_role"
+	// TargetUser is a fixed user
+	TargetUser TargetKind = "user"
+)
+
+// IsValid reports whether the target kind is known
+func (k TargetKind) IsValid() bool {
+	switch k {
+	case TargetManager, TargetSkipLevelManager, TargetDepartmentManager, TargetTeamLeader, TargetOrgRole, TargetUser:
+		return true
+	}
+	return false
+}
+
+// Target is one candidate in an escalation level's fallback chain
+type Target struct {
+	Kind TargetKind `json:"kind"`
+	// Role is required for org_role targets
+	Role string `json:"role,omitempty"`
+	// UserID is required for user targets
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// Level is one tier of escalation. Targets are tried in order and the first
+// one that resolves to an eligible user receives the escalation.
+type Level struct {
+	Targets []Target `json:"targets"`
+}
+
+// Policy is an escalation matrix: who to notify at each level for a subject user
+type Policy struct {
+	Code   string  `json:"code"`
+	Name   string  `json:"name"`
+	Levels []Level `json:"levels"`
+}
+
+// DefaultPolicyCode is the policy used when a feature does not name one
+const DefaultPolicyCode = "default"
+
+// MaxLevels caps how many escalation levels a policy may define
+const MaxLevels = 5
+
+var policyCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// orgRoles are the organization_users roles an org_role target may name
+var orgRoles = map[string]bool{"owner": true, "admin": true, "manager": true}
+
+// DefaultPolicy escalates to the direct manager, then the skip-level manager,
+// falling back to organization administrators and owners
+func DefaultPolicy() Policy {
+	return Policy{
+		Code: DefaultPolicyCode,
+		Name: "Default",
+		Levels: []Level{
+			{Targets: []Target{
+				{Kind: TargetManager},
+				{Kind: TargetDepartmentManager},
+				{Kind: TargetOrgRole, Role: "admin"},
+			}},
+			{Targets: []Target{
+				{Kind: TargetSkipLevelManager},
+				{Kind: TargetOrgRole, Role: "owner"},
+			}},
+		},
+	}
+}
+
+// Validate checks the policy code, level count and that every target is complete
+func (p Policy) Validate() error {
+	if !policyCodePattern.MatchString(p.Code) {
+		return fmt.Errorf("invalid policy code %q: use 1-50 lowercase letters, digits or underscores", p.Code)
+	}
+	if len(p.Levels) == 0 {
+		return errors.New("policy must define at least one level")
+	}
+	if len(p.Levels) > MaxLevels {
+		return fmt.Errorf("policy may define at most %d levels", MaxLevels)
+	}
+	for i, level := range p.Levels {
+		if len(level.Targets) == 0 {
+			return fmt.Errorf("level %d has no targets", i+1)
+		}
+		for _, t := range level.Targets {
+			if !t.Kind.IsValid() {
+				return fmt.Errorf("level %d: unknown target kind %q", i+1, t.Kind)
+			}
+			if t.Kind == TargetOrgRole && !orgRoles[t.Role] {
+				return fmt.Errorf("level %d: org_role target needs role owner, admin or manager", i+1)
+			}
+			if t.Kind == TargetUser && (t.UserID == nil || *t.UserID == uuid.Nil) {
+				return fmt.Errorf("level %d: user target needs user_id", i+1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package hierarchy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrPolicyNotFound is returned when an organization has no policy with the requested code
+var ErrPolicyNotFound = errors.New("escalation policy not found")
+
+// Store loads escalation policies and resolves them against the HR structure,
+// caching hierarchy lookups. It is the entry point for features that need to
+// notify someone up the chain.
+type Store struct {
+	db        *sql.DB
+	directory *CachedDirectory
+	resolver  *Resolver
+}
+
+// NewStore creates a store backed by the database
+func NewStore(db *sql.DB) *Store {
+	directory := NewCachedDirectory(NewSQLDirectory(db), DefaultCacheTTL)
+	return &Store{
+		db:        db,
+		directory: directory,
+		resolver:  NewResolver(directory),
+	}
+}
+
+// Resolver returns the cached resolver used by the store
+func (s *Store) Resolver() *Resolver {
+	return s.resolver
+}
+
+// Invalidate drops cached hierarchy lookups for the organization
+func (s *Store) Invalidate(orgID uuid.UUID) {
+	s.directory.Invalidate(orgID)
+}
+
+// Policy loads an organization's policy by code. The default code falls back
+// to DefaultPolicy when the organization has not configured its own.
+func (s *Store) Policy(ctx context.Context, orgID uuid.UUID, code string) (Policy, error) {
+	query := `
+		SELECT code, name, levels
+		FROM escalation_policies
+		WHERE organization_id = $1 AND code = $2
+	`
+
+	var policy Policy
+	var levelsJSON []byte
+	err := s.db.QueryRowContext(ctx, query, orgID, code).Scan(&policy.Code, &policy.Name, &levelsJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if code == DefaultPolicyCode {
+				return DefaultPolicy(), nil
+			}
+			return Policy{}, fmt.Errorf("%w: %s", ErrPolicyNotFound, code)
+		}
+		return Policy{}, fmt.Errorf("failed to load escalation policy: %w", err)
+	}
+
+	if err := json.Unmarshal(levelsJSON, &policy.Levels); err != nil {
+		return Policy{}, fmt.Errorf("invalid escalation policy levels: %w", err)
+	}
+	return policy, nil
+}
+
+// Escalate resolves the named policy for the subject user
+func (s *Store) Escalate(ctx context.Context, orgID, subjectID uuid.UUID, code string) ([]Escalation, error) {
+	policy, err := s.Policy(ctx, orgID, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolver.Escalate(ctx, orgID, subjectID, policy)
+}
@@ -0,0 +1,109 @@
+package masking
+
+import "strings"
+
+var (
+	personNameColumns = map[string]bool{
+		"first_name": true, "last_name": true, "middle_name": true, "full_name": true,
+		"contact_name": true, "display_name": true, "employee_name": true, "visitor_name": true,
+		"candidate_name": true, "customer_name": true, "vendor_name": true, "partner_name": true,
+		"host_name": true, "signer_name": true,
+	}
+	companyNameColumns = map[string]bool{
+		"legal_name": true, "company_name": true, "trade_name": true,
+	}
+	streetColumns = map[string]bool{
+		"street": true, "street2": true, "address": true, "address_line1": true, "address_line2": true,
+	}
+	identifierColumns = map[string]bool{
+		"tax_id": true, "vat": true, "vat_number": true, "iban": true, "swift": true, "bic": true,
+		"ssn": true, "national_id": true, "passport_number": true, "bank_account": true,
+		"account_number": true, "payment_reference": true, "zip": true, "postal_code": true,
+		"license_plate": true, "id_number": true,
+	}
+	secretColumns = map[string]bool{
+		"password": true, "password_hash": true, "secret": true, "api_key": true, "token": true,
+		"access_token": true, "refresh_token": true, "webhook_secret": true,
+	}
+	textColumns = map[string]bool{
+		"note": true, "notes": true, "comment": true, "comments": true, "description": true,
+		"body": true, "message": true, "user_comment": true, "internal_notes": true,
+	}
+	// nameTables are the tables whose plain name column holds a person's or
+	// an organization's name rather than that of a product, stage or report
+	nameTables = map[string]Kind{
+		"contacts":      KindPersonName,
+		"employees":     KindPersonName,
+		"candidates":    KindPersonName,
+		"visitors":      KindPersonName,
+		"companies":     KindCompanyName,
+		"organizations": KindCompanyName,
+		"leads":         KindCompanyName,
+	}
+	amountWords = []string{
+		"amount", "price", "total", "revenue", "cost", "balance", "debit", "credit",
+		"salary", "wage", "subtotal", "budget",
+	}
+)
+
+// Classify returns how a column is masked from its table, name and
+// PostgreSQL data type, and false when it is copied unchanged. Keys and
+// other uuid columns are always copied unchanged.
+func Classify(table, column, dataType string) (Kind, bool) {
+	switch dataType {
+	case "uuid", "boolean", "date", "timestamp with time zone", "timestamp without time zone", "interval",
+		"ARRAY", "USER-DEFINED", "bytea":
+		return "", false
+	case "inet", "cidr":
+		return KindIPAddress, true
+	case "jsonb", "json":
+		if column == "custom_fields" {
+			return KindJSON, true
+		}
+		return "", false
+	case "numeric", "double precision", "real", "money":
+		if isAmount(column) {
+			return KindAmount, true
+		}
+		return "", false
+	case "integer", "bigint", "smallint":
+		return "", false
+	}
+
+	switch {
+	case column == "email" || strings.HasSuffix(column, "_email"):
+		return KindEmail, true
+	case column == "phone" || column == "mobile" || column == "fax" || strings.HasSuffix(column, "_phone"):
+		return KindPhone, true
+	case column == "ip_address":
+		return KindIPAddress, true
+	case personNameColumns[column]:
+		return KindPersonName, true
+	case companyNameColumns[column]:
+		return KindCompanyName, true
+	case column == "name" || column == "complete_name":
+		kind, ok := nameTables[table]
+		return kind, ok
+	case table == "organizations" && column == "slug":
+		return KindIdentifier, true
+	case streetColumns[column]:
+		return KindStreet, true
+	case identifierColumns[column]:
+		return KindIdentifier, true
+	case secretColumns[column] || strings.HasSuffix(column, "_secret") || strings.HasSuffix(column, "_token"):
+		return KindSecret, true
+	case textColumns[column] || strings.HasSuffix(column, "_note") || strings.HasSuffix(column, "_notes"):
+		return KindText, true
+	}
+	return "", false
+}
+
+func isAmount(column string) bool {
+	for _, word := range amountWords {
+		if column == word || strings.HasPrefix(column, word+"_") || strings.HasSuffix(column, "_"+word) ||
+			strings.Contains(column, "_"+word+"_") {
+			return true
+		}
+	}
+	return false
+}
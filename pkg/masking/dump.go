@@ -0,0 +1,371 @@
+package masking
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Column is a column copied by a dump
+type Column struct {
+	Name     string
+	DataType string
+	Kind     Kind
+	Masked   bool
+}
+
+// TableDump is what a dump copied from one table
+type TableDump struct {
+	Table  string   `json:"table"`
+	Rows   int64    `json:"rows"`
+	Masked []string `json:"masked_columns,omitempty"`
+}
+
+// Dumper writes a masked copy of an organization's data as a SQL script of
+// COPY blocks, loadable with psql into an empty, migrated database. Every row
+// of every table carrying the organization's id is copied, keys unchanged, so
+// record counts and references between the copied rows are preserved. Rows
+// outside the organization, such as users, currencies and countries, are
+// expected to exist in the target already.
+type Dumper struct {
+	db     *sql.DB
+	masker *Masker
+	// DisableTriggers loads the copy with triggers and foreign key checks
+	// off, for schemas with circular references. It needs a superuser.
+	DisableTriggers bool
+}
+
+func NewDumper(db *sql.DB, masker *Masker) *Dumper {
+	return &Dumper{db: db, masker: masker}
+}
+
+type dumpTable struct {
+	name    string
+	filter  string
+	columns []Column
+}
+
+// Dump writes the masked copy of the organization to w from a single
+// snapshot of the database
+func (d *Dumper) Dump(ctx context.Context, orgID uuid.UUID, w io.Writer) ([]TableDump, error) {
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin dump: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1)", orgID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("organization %s not found", orgID)
+	}
+
+	tables, err := d.tables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	tables, err = orderByReferences(ctx, tx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "-- Masked copy of organization %s\n", orgID)
+	fmt.Fprintln(out, "SET client_encoding = 'UTF8';")
+	fmt.Fprintln(out, "BEGIN;")
+	if d.DisableTriggers {
+		fmt.Fprintln(out, "SET LOCAL session_replication_role = replica;")
+	}
+
+	dumps := make([]TableDump, 0, len(tables))
+	for _, table := range tables {
+		dump, err := d.dumpTable(ctx, tx, table, orgID, out)
+		if err != nil {
+			return dumps, err
+		}
+		dumps = append(dumps, dump)
+	}
+
+	fmt.Fprintln(out, "COMMIT;")
+	if err := out.Flush(); err != nil {
+		return dumps, fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	return dumps, nil
+}
+
+// tables lists the organizations table and every table of the current schema
+// carrying an organization_id column, with the columns a load can write
+func (d *Dumper) tables(ctx context.Context, tx *sql.Tx) ([]*dumpTable, error) {
+	query := `
+		SELECT c.table_name, c.column_name, c.data_type
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema()
+			AND t.table_type = 'BASE TABLE'
+			AND c.is_generated = 'NEVER'
+			AND (c.table_name = 'organizations' OR EXISTS (
+				SELECT 1 FROM information_schema.columns o
+				WHERE o.table_schema = c.table_schema
+					AND o.table_name = c.table_name
+					AND o.column_name = 'organization_id'
+			))
+		ORDER BY c.table_name, c.ordinal_position
+	`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []*dumpTable
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if len(tables) == 0 || tables[len(tables)-1].name != table {
+			filter := "organization_id = $1"
+			if table == "organizations" {
+				filter = "id = $1"
+			}
+			tables = append(tables, &dumpTable{name: table, filter: filter})
+		}
+		kind, masked := Classify(table, column, dataType)
+		current := tables[len(tables)-1]
+		current.columns = append(current.columns, Column{Name: column, DataType: dataType, Kind: kind, Masked: masked})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during column iteration: %w", err)
+	}
+	rows.Close()
+
+	if err := d.applyClassificationRules(ctx, tx, tables); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// applyClassificationRules masks the text columns the data privacy layer
+// marks as holding personal data that the naming rules did not catch
+func (d *Dumper) applyClassificationRules(ctx context.Context, tx *sql.Tx, tables []*dumpTable) error {
+	var present bool
+	if err := tx.QueryRowContext(ctx, "SELECT to_regclass('data_classification_rules') IS NOT NULL").Scan(&present); err != nil {
+		return fmt.Errorf("failed to look up classification rules: %w", err)
+	}
+	if !present {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT table_name, column_name FROM data_classification_rules WHERE contains_pii")
+	if err != nil {
+		return fmt.Errorf("failed to list classification rules: %w", err)
+	}
+	defer rows.Close()
+
+	pii := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("failed to scan classification rule: %w", err)
+		}
+		pii[table+"."+column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during classification rule iteration: %w", err)
+	}
+
+	for _, table := range tables {
+		for i, column := range table.columns {
+			if column.Masked || !pii[table.name+"."+column.Name] {
+				continue
+			}
+			switch column.DataType {
+			case "text", "character varying", "character":
+				table.columns[i].Kind, table.columns[i].Masked = KindIdentifier, true
+			}
+		}
+	}
+	return nil
+}
+
+// orderByReferences sorts the tables so that each comes after the tables its
+// foreign keys reference. Tables in a reference cycle keep their name order
+// at the end and need DisableTriggers to load.
+func orderByReferences(ctx context.Context, tx *sql.Tx, tables []*dumpTable) ([]*dumpTable, error) {
+	query := `
+		SELECT DISTINCT src.relname, dst.relname
+		FROM pg_constraint k
+		JOIN pg_class src ON src.oid = k.conrelid
+		JOIN pg_class dst ON dst.oid = k.confrelid
+		JOIN pg_namespace n ON n.oid = src.relnamespace
+		WHERE k.contype = 'f'
+			AND n.nspname = current_schema()
+			AND k.conrelid <> k.confrelid
+	`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	byName := make(map[string]*dumpTable, len(tables))
+	for _, table := range tables {
+		byName[table.name] = table
+	}
+	references := make(map[string][]string)
+	for rows.Next() {
+		var src, dst string
+		if err := rows.Scan(&src, &dst); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if byName[src] != nil && byName[dst] != nil {
+			references[src] = append(references[src], dst)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during foreign key iteration: %w", err)
+	}
+
+	return sortTables(tables, references), nil
+}
+
+// sortTables orders the tables after the ones they reference, by name among
+// tables that are free to go next
+func sortTables(tables []*dumpTable, references map[string][]string) []*dumpTable {
+	pending := make(map[string]int, len(tables))
+	referencedBy := make(map[string][]string)
+	for _, table := range tables {
+		for _, dst := range references[table.name] {
+			pending[table.name]++
+			referencedBy[dst] = append(referencedBy[dst], table.name)
+		}
+	}
+
+	byName := make(map[string]*dumpTable, len(tables))
+	var ready []string
+	for _, table := range tables {
+		byName[table.name] = table
+		if pending[table.name] == 0 {
+			ready = append(ready, table.name)
+		}
+	}
+
+	ordered := make([]*dumpTable, 0, len(tables))
+	done := make(map[string]bool, len(tables))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, byName[name])
+		done[name] = true
+		for _, src := range referencedBy[name] {
+			pending[src]--
+			if pending[src] == 0 {
+				ready = append(ready, src)
+			}
+		}
+	}
+
+	var cyclic []string
+	for _, table := range tables {
+		if !done[table.name] {
+			cyclic = append(cyclic, table.name)
+		}
+	}
+	sort.Strings(cyclic)
+	for _, name := range cyclic {
+		ordered = append(ordered, byName[name])
+	}
+	return ordered
+}
+
+// dumpTable writes one table's rows as a COPY block, masking its classified
+// columns
+func (d *Dumper) dumpTable(ctx context.Context, tx *sql.Tx, table *dumpTable, orgID uuid.UUID, out *bufio.Writer) (TableDump, error) {
+	dump := TableDump{Table: table.name}
+
+	names := make([]string, len(table.columns))
+	selects := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		names[i] = pq.QuoteIdentifier(column.Name)
+		selects[i] = names[i] + "::text"
+		if column.Masked {
+			dump.Masked = append(dump.Masked, column.Name)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(selects, ", "), pq.QuoteIdentifier(table.name), table.filter)
+	rows, err := tx.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return dump, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	fmt.Fprintf(out, "\nCOPY %s (%s) FROM stdin;\n", pq.QuoteIdentifier(table.name), strings.Join(names, ", "))
+
+	values := make([]sql.NullString, len(table.columns))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return dump, fmt.Errorf("failed to scan %s row: %w", table.name, err)
+		}
+		for i, value := range values {
+			if i > 0 {
+				out.WriteByte('\t')
+			}
+			if !value.Valid {
+				out.WriteString(`\N`)
+				continue
+			}
+			text := value.String
+			if column := table.columns[i]; column.Masked {
+				text = d.masker.Mask(column.Kind, text)
+			}
+			writeCopyValue(out, text)
+		}
+		out.WriteByte('\n')
+		dump.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return dump, fmt.Errorf("error during %s iteration: %w", table.name, err)
+	}
+
+	out.WriteString("\\.\n")
+	return dump, nil
+}
+
+// writeCopyValue writes a value in the COPY text format
+func writeCopyValue(out *bufio.Writer, value string) {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			out.WriteByte(c)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package masking produces sanitized copies of an organization's data for
+// staging and support reproduction. Values are replaced deterministically
+// under a secret key, so a value masks the same way wherever it appears and
+// lookups by email or reference still match across tables. Primary and
+// foreign keys are never masked, which keeps the copy referentially intact.
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Kind is how a column's values are masked
+type Kind string
+
+const (
+	// KindPersonName replaces a person's name with a fake one
+	KindPersonName Kind = "person_name"
+	// KindCompanyName replaces an organization's name with a fake one
+	KindCompanyName Kind = "company_name"
+	// KindEmail replaces an email with a hash of it at example.com
+	KindEmail Kind = "email"
+	// KindPhone replaces every digit, keeping the number's format
+	KindPhone Kind = "phone"
+	// KindStreet replaces a street line with a fake one
+	KindStreet Kind = "street"
+	// KindIdentifier replaces every letter and digit of tax numbers, bank
+	// accounts, payment references and the like, keeping their format
+	KindIdentifier Kind = "identifier"
+	// KindSecret replaces tokens and password hashes, keeping their length
+	KindSecret Kind = "secret"
+	// KindIPAddress replaces an address with one from a documentation range
+	KindIPAddress Kind = "ip_address"
+	// KindText replaces free text with filler of the same number of words
+	KindText Kind = "text"
+	// KindAmount scales a monetary amount by the organization's factor
+	KindAmount Kind = "amount"
+	// KindJSON masks every string value of a JSON document as text
+	KindJSON Kind = "json"
+)
+
+// maxTextWords caps the filler written for long free text
+const maxTextWords = 200
+
+var (
+	firstNames = []string{
+		"Alex", "Avery", "Blair", "Casey", "Charlie", "Dana", "Drew", "Eden",
+		"Emery", "Finley", "Gray", "Harper", "Hayden", "Jamie", "Jordan", "Kai",
+		"Kendall", "Lane", "Logan", "Morgan", "Noel", "Parker", "Quinn", "Reese",
+		"Riley", "Rowan", "Sage", "Sam", "Skyler", "Taylor", "Terry", "Wren",
+	}
+	lastNames = []string{
+		"Abbott", "Bennett", "Carver", "Dalton", "Ellis", "Fletcher", "Garner", "Hale",
+		"Ingram", "Jensen", "Keller", "Lambert", "Mercer", "Nolan", "Osborne", "Pryor",
+		"Quincy", "Rhodes", "Sutton", "Thorne", "Underwood", "Vance", "Walsh", "Yates",
+		"Archer", "Brooks", "Chandler", "Doyle", "Everett", "Foster", "Gibbs", "Holt",
+	}
+	companyWords = []string{
+		"Amber", "Beacon", "Cedar", "Delta", "Ember", "Falcon", "Granite", "Harbor",
+		"Iris", "Juniper", "Keystone", "Lumen", "Maple", "Nimbus", "Orchard", "Pioneer",
+		"Quarry", "Ridge", "Summit", "Timber", "Union", "Vertex", "Willow", "Zenith",
+	}
+	companyIndustries = []string{
+		"Trading", "Logistics", "Supply", "Foods", "Systems", "Industries",
+		"Partners", "Retail", "Labs", "Works", "Holdings", "Services",
+	}
+	streetNames = []string{
+		"Oak", "Pine", "Elm", "Lake", "Hill", "Park", "Mill", "River",
+		"Church", "Station", "Market", "Bridge", "Forest", "Meadow", "Spring", "Valley",
+	}
+	streetTypes = []string{"Street", "Avenue", "Road", "Lane", "Drive", "Way"}
+	fillerWords = []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit",
+		"sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et",
+		"dolore", "magna", "aliqua", "enim", "ad", "minim", "veniam", "quis",
+	}
+)
+
+// Masker masks the values of one organization
+type Masker struct {
+	key    []byte
+	factor float64
+}
+
+// New returns a masker for the organization. The same key and organization
+// always mask a value the same way; without the key the originals cannot be
+// recovered by hashing guesses. Amounts are scaled by a factor between 0.8
+// and 1.25 derived from both, never within 3% of the original, so that
+// totals, balances and ledgers stay consistent while real figures are hidden.
+func New(key []byte, orgID uuid.UUID) *Masker {
+	m := &Masker{key: key}
+	span := float64(m.uint64("factor", orgID.String())%4400) / 10000 // 0 to 0.44
+	factor := 0.8 + span
+	if factor > 0.97 && factor < 1.03 {
+		factor += 0.06
+	}
+	m.factor = factor
+	return m
+}
+
+// Factor is the scale applied to amounts
+func (m *Masker) Factor() float64 {
+	return m.factor
+}
+
+// Mask masks a value, as its PostgreSQL text representation, by kind
+func (m *Masker) Mask(kind Kind, value string) string {
+	if value == "" {
+		return value
+	}
+	switch kind {
+	case KindPersonName:
+		return m.PersonName(value)
+	case KindCompanyName:
+		return m.CompanyName(value)
+	case KindEmail:
+		return m.Email(value)
+	case KindPhone, KindIdentifier, KindSecret:
+		return m.Scramble(string(kind), value)
+	case KindStreet:
+		return m.Street(value)
+	case KindIPAddress:
+		return m.IPAddress(value)
+	case KindText:
+		return m.Text(value)
+	case KindAmount:
+		return m.Amount(value)
+	case KindJSON:
+		return m.JSON(value)
+	}
+	return value
+}
+
+// PersonName returns a fake first and last name
+func (m *Masker) PersonName(value string) string {
+	h := m.uint64(string(KindPersonName), value)
+	return firstNames[h%uint64(len(firstNames))] + " " + lastNames[(h>>16)%uint64(len(lastNames))]
+}
+
+// CompanyName returns a fake company name. A short hash suffix keeps names
+// that must be unique distinct.
+func (m *Masker) CompanyName(value string) string {
+	h := m.uint64(string(KindCompanyName), value)
+	return fmt.Sprintf("%s %s %04X",
+		companyWords[h%uint64(len(companyWords))],
+		companyIndustries[(h>>16)%uint64(len(companyIndustries))],
+		(h>>32)&0xFFFF)
+}
+
+// Email returns a hash of the address at example.com. Addresses are compared
+// case-insensitively, so they are hashed lower-cased.
+func (m *Masker) Email(value string) string {
+	return "user-" + m.hex(string(KindEmail), strings.ToLower(strings.TrimSpace(value)), 12) + "@example.com"
+}
+
+// Street returns a fake street line
+func (m *Masker) Street(value string) string {
+	h := m.uint64(string(KindStreet), value)
+	return fmt.Sprintf("%d %s %s", 1+h%999,
+		streetNames[(h>>16)%uint64(len(streetNames))],
+		streetTypes[(h>>32)%uint64(len(streetTypes))])
+}
+
+// Scramble replaces every digit with a digit and every letter with a letter
+// of the same case, keeping punctuation and length, so the value still
+// passes format checks
+func (m *Masker) Scramble(scope, value string) string {
+	stream := m.stream(scope, value)
+	runes := []rune(value)
+	for i, r := range runes {
+		b := stream(i)
+		switch {
+		case r >= '0' && r <= '9':
+			runes[i] = rune('0' + b%10)
+		case r >= 'a' && r <= 'z':
+			runes[i] = rune('a' + b%26)
+		case r >= 'A' && r <= 'Z':
+			runes[i] = rune('A' + b%26)
+		case unicode.IsLetter(r):
+			runes[i] = rune('a' + b%26)
+		}
+	}
+	return string(runes)
+}
+
+// IPAddress returns an address from the documentation ranges, keeping the
+// address family and any network prefix
+func (m *Masker) IPAddress(value string) string {
+	addr, suffix := value, ""
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		addr, suffix = value[:i], value[i:]
+	}
+	h := m.uint64(string(KindIPAddress), addr)
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return fmt.Sprintf("2001:db8::%x:%x", (h>>16)&0xFFFF, h&0xFFFF) + suffix
+	}
+	return fmt.Sprintf("198.51.100.%d", 1+h%254) + suffix
+}
+
+// Text returns filler with as many words as the original, up to a limit
+func (m *Masker) Text(value string) string {
+	count := len(strings.Fields(value))
+	if count == 0 {
+		return value
+	}
+	if count > maxTextWords {
+		count = maxTextWords
+	}
+	stream := m.stream(string(KindText), value)
+	words := make([]string, count)
+	for i := range words {
+		words[i] = fillerWords[int(stream(i))%len(fillerWords)]
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + "."
+}
+
+// Amount scales a decimal amount, keeping its number of decimal places.
+// Values that are not numbers are returned unchanged.
+func (m *Masker) Amount(value string) string {
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return value
+	}
+	decimals := 0
+	if i := strings.IndexByte(value, '.'); i >= 0 && !strings.ContainsAny(value, "eE") {
+		decimals = len(value) - i - 1
+	}
+	return strconv.FormatFloat(amount*m.factor, 'f', decimals, 64)
+}
+
+// JSON masks every string value of a JSON document as text, keeping its
+// keys, numbers and structure. Invalid documents are returned unchanged.
+func (m *Masker) JSON(value string) string {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return value
+	}
+	masked, err := json.Marshal(m.maskJSON(doc))
+	if err != nil {
+		return value
+	}
+	return string(masked)
+}
+
+func (m *Masker) maskJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if strings.Contains(v, "@") {
+			return m.Email(v)
+		}
+		return m.Scramble(string(KindJSON), v)
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = m.maskJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = m.maskJSON(value)
+		}
+	}
+	return v
+}
+
+func (m *Masker) sum(scope, value string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func (m *Masker) uint64(scope, value string) uint64 {
+	return binary.BigEndian.Uint64(m.sum(scope, value))
+}
+
+func (m *Masker) hex(scope, value string, n int) string {
+	return hex.EncodeToString(m.sum(scope, value))[:n]
+}
+
+// stream returns pseudo-random bytes derived from the value, extended with
+// further blocks for values longer than one hash
+func (m *Masker) stream(scope, value string) func(i int) byte {
+	blocks := [][]byte{m.sum(scope, value)}
+	return func(i int) byte {
+		for i/sha256.Size >= len(blocks) {
+			blocks = append(blocks, m.sum(scope+strconv.Itoa(len(blocks)), value))
+		}
+		return blocks[i/sha256.Size][i%sha256.Size]
+	}
+}
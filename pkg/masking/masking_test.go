@@ -0,0 +1,156 @@
+package masking
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMasker() *Masker {
+	return New([]byte("staging-key"), uuid.MustParse("6f1c3c1e-8a0b-4a57-9a3f-2f6c1d1e0b77"))
+}
+
+func TestMaskIsDeterministicPerKey(t *testing.T) {
+	m := newTestMasker()
+	other := New([]byte("another-key"), uuid.MustParse("6f1c3c1e-8a0b-4a57-9a3f-2f6c1d1e0b77"))
+
+	assert.Equal(t, m.Mask(KindPersonName, "Jane Doe"), m.Mask(KindPersonName, "Jane Doe"))
+	assert.Equal(t, m.Email("Jane.Doe@Acme.com"), m.Email("jane.doe@acme.com "))
+	assert.NotEqual(t, m.Email("jane.doe@acme.com"), other.Email("jane.doe@acme.com"))
+	assert.NotEqual(t, m.Email("jane.doe@acme.com"), m.Email("john.doe@acme.com"))
+}
+
+func TestMaskReplacesPersonalData(t *testing.T) {
+	m := newTestMasker()
+
+	assert.Regexp(t, `^user-[0-9a-f]{12}@example\.com$`, m.Mask(KindEmail, "jane.doe@acme.com"))
+	assert.NotContains(t, m.Mask(KindPersonName, "Jane Doe"), "Jane")
+	assert.Regexp(t, `^[A-Za-z]+ [A-Za-z]+ [0-9A-F]{4}$`, m.Mask(KindCompanyName, "Acme Corporation"))
+	assert.Regexp(t, `^\d+ [A-Za-z]+ [A-Za-z]+$`, m.Mask(KindStreet, "12 Rue de Rivoli"))
+	assert.Equal(t, "198.51.100.", m.Mask(KindIPAddress, "10.1.2.3")[:11])
+	assert.True(t, strings.HasPrefix(m.Mask(KindIPAddress, "fe80::1/64"), "2001:db8::"))
+	assert.True(t, strings.HasSuffix(m.Mask(KindIPAddress, "fe80::1/64"), "/64"))
+	assert.Empty(t, m.Mask(KindEmail, ""))
+}
+
+func TestScrambleKeepsFormat(t *testing.T) {
+	m := newTestMasker()
+	shape := func(s string) string {
+		s = regexp.MustCompile(`[0-9]`).ReplaceAllString(s, "9")
+		s = regexp.MustCompile(`[A-Z]`).ReplaceAllString(s, "A")
+		return regexp.MustCompile(`[a-z]`).ReplaceAllString(s, "a")
+	}
+
+	for _, value := range []string{"+33 (0)1 42-68-53-00", "FR76 3000 6000 0112 3456 7890 189", "INV/2025/0042"} {
+		masked := m.Mask(KindIdentifier, value)
+		assert.Equal(t, shape(value), shape(masked))
+		assert.NotEqual(t, value, masked)
+	}
+}
+
+func TestTextKeepsWordCount(t *testing.T) {
+	m := newTestMasker()
+
+	masked := m.Mask(KindText, "Called back about the late delivery, wants a refund")
+	assert.Len(t, strings.Fields(masked), 9)
+	assert.NotContains(t, masked, "refund")
+	assert.Len(t, strings.Fields(m.Text(strings.Repeat("word ", 500))), maxTextWords)
+}
+
+func TestAmountScalesConsistently(t *testing.T) {
+	m := newTestMasker()
+	factor := m.Factor()
+
+	assert.True(t, factor >= 0.8 && factor <= 1.25)
+	assert.False(t, factor > 0.97 && factor < 1.03)
+
+	masked := m.Mask(KindAmount, "1250.50")
+	assert.Regexp(t, `^\d+\.\d{2}$`, masked)
+	value, err := strconv.ParseFloat(masked, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, 1250.50*factor, value, 0.005)
+	assert.Equal(t, "0.00", m.Mask(KindAmount, "0.00"))
+	assert.Equal(t, "NaN", m.Mask(KindAmount, "NaN"))
+}
+
+func TestJSONMasksStringValues(t *testing.T) {
+	m := newTestMasker()
+
+	masked := m.Mask(KindJSON, `{"nickname":"Janie","email":"jane@acme.com","score":7,"tags":["vip"]}`)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(masked), &doc))
+	assert.NotEqual(t, "Janie", doc["nickname"])
+	assert.Len(t, doc["nickname"], 5)
+	assert.Equal(t, m.Email("jane@acme.com"), doc["email"])
+	assert.Equal(t, 7.0, doc["score"])
+	assert.Len(t, doc["tags"], 1)
+	assert.Equal(t, "not json", m.Mask(KindJSON, "not json"))
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		table, column, dataType string
+		kind                    Kind
+		masked                  bool
+	}{
+		{"contacts", "email", "character varying", KindEmail, true},
+		{"leads", "contact_email", "text", KindEmail, true},
+		{"contacts", "mobile", "text", KindPhone, true},
+		{"contacts", "name", "text", KindPersonName, true},
+		{"companies", "name", "text", KindCompanyName, true},
+		{"products", "name", "text", "", false},
+		{"leads", "contact_name", "text", KindPersonName, true},
+		{"contacts", "street", "text", KindStreet, true},
+		{"contacts", "zip", "text", KindIdentifier, true},
+		{"invoices", "payment_reference", "text", KindIdentifier, true},
+		{"webhooks", "signing_secret", "text", KindSecret, true},
+		{"contacts", "comment", "text", KindText, true},
+		{"sign_in_events", "ip_address", "inet", KindIPAddress, true},
+		{"invoices", "amount_total", "numeric", KindAmount, true},
+		{"journal_entry_lines", "debit", "numeric", KindAmount, true},
+		{"invoice_lines", "quantity", "numeric", "", false},
+		{"leads", "probability", "numeric", "", false},
+		{"contacts", "custom_fields", "jsonb", KindJSON, true},
+		{"contacts", "id", "uuid", "", false},
+		{"contacts", "email", "ARRAY", "", false},
+	}
+
+	for _, tt := range tests {
+		kind, masked := Classify(tt.table, tt.column, tt.dataType)
+		assert.Equal(t, tt.masked, masked, "%s.%s", tt.table, tt.column)
+		assert.Equal(t, tt.kind, kind, "%s.%s", tt.table, tt.column)
+	}
+}
+
+func TestSortTablesPutsReferencedTablesFirst(t *testing.T) {
+	tables := []*dumpTable{{name: "invoice_lines"}, {name: "invoices"}, {name: "contacts"}, {name: "organizations"}, {name: "a_cycle"}, {name: "b_cycle"}}
+	references := map[string][]string{
+		"invoice_lines": {"invoices"},
+		"invoices":      {"contacts", "organizations"},
+		"contacts":      {"organizations"},
+		"a_cycle":       {"b_cycle"},
+		"b_cycle":       {"a_cycle"},
+	}
+
+	var names []string
+	for _, table := range sortTables(tables, references) {
+		names = append(names, table.name)
+	}
+	assert.Equal(t, []string{"organizations", "contacts", "invoices", "invoice_lines", "a_cycle", "b_cycle"}, names)
+}
+
+func TestWriteCopyValueEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	writeCopyValue(out, "line one\nline\ttwo \\ end\r")
+	require.NoError(t, out.Flush())
+	assert.Equal(t, `line one\nline\ttwo \\ end\r`, buf.String())
+}
@@ -0,0 +1,33 @@
+// Package metering attributes usage, such as emails sent, to the
+// organization it is billed to, and lets modules read the metered usage
+package metering
+
+import (
+	"context"
+
+	"github.com/KevTiv/alieze-erp/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// WithOrganization attributes the usage recorded under ctx to an
+// organization. Background jobs use it where there is no authenticated
+// request.
+func WithOrganization(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// OrganizationFrom returns the organization usage recorded under ctx is
+// attributed to: the one set with WithOrganization, else the one of the
+// authenticated request
+func OrganizationFrom(ctx context.Context) (uuid.UUID, bool) {
+	if orgID, ok := ctx.Value(contextKey{}).(uuid.UUID); ok && orgID != uuid.Nil {
+		return orgID, true
+	}
+	if authCtx, err := auth.FromContext(ctx); err == nil && authCtx.OrganizationID != uuid.Nil {
+		return authCtx.OrganizationID, true
+	}
+	return uuid.Nil, false
+}
@@ -0,0 +1,24 @@
+package metering
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Total is an organization's usage of a metric over a period
+type Total struct {
+	Metric string `json:"metric"`
+	// Gauge metrics are levels measured once a day, such as stored records;
+	// their total is the peak daily value. Other metrics are counts.
+	Gauge bool  `json:"gauge"`
+	Total int64 `json:"total"`
+}
+
+// Reader reads organizations' metered usage for the modules reporting it
+type Reader interface {
+	// Totals returns the organization's usage of every metric between two
+	// UTC days, inclusive, as of the last flush of the counters
+	Totals(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]Total, error)
+}
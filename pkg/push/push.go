@@ -0,0 +1,79 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is a push message for the devices of some users
+type Notification struct {
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	UserIDs        []uuid.UUID `json:"user_ids"`
+	Title          string      `json:"title"`
+	Body           string      `json:"body"`
+	// Data is passed to the app untouched, e.g. to open the right screen
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Notifier delivers push notifications
+type Notifier interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// RelayNotifier posts notifications as JSON to a push relay, which keeps the
+// users' device registrations and talks to APNs and FCM
+type RelayNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewRelayNotifier creates a notifier for the relay at url. A non-empty token
+// is sent as a bearer token.
+func NewRelayNotifier(url, token string) *RelayNotifier {
+	return &RelayNotifier{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts the notification to the relay
+func (n *RelayNotifier) Send(ctx context.Context, notification Notification) error {
+	if len(notification.UserIDs) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode push notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push relay returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
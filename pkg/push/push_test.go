@@ -0,0 +1,55 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayNotifierSend(t *testing.T) {
+	var received Notification
+	var authorization string
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer relay.Close()
+
+	userID := uuid.New()
+	notifier := NewRelayNotifier(relay.URL, "secret")
+	err := notifier.Send(context.Background(), Notification{
+		UserIDs: []uuid.UUID{userID},
+		Title:   "Dispatch",
+		Body:    "Gate code is 4411",
+		Data:    map[string]string{"route_id": "r1"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, []uuid.UUID{userID}, received.UserIDs)
+	assert.Equal(t, "r1", received.Data["route_id"])
+}
+
+func TestRelayNotifierErrors(t *testing.T) {
+	calls := 0
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "unknown device", http.StatusBadRequest)
+	}))
+	defer relay.Close()
+
+	notifier := NewRelayNotifier(relay.URL, "")
+	require.NoError(t, notifier.Send(context.Background(), Notification{}), "nothing to send without recipients")
+	assert.Zero(t, calls)
+
+	err := notifier.Send(context.Background(), Notification{UserIDs: []uuid.UUID{uuid.New()}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400: unknown device")
+}
@@ -0,0 +1,470 @@
+// Package query validates boolean filter trees over an entity's fields,
+// including its custom fields and tags, and compiles them to SQL
+// conditions whose values are always bound as arguments.
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidQuery is returned for filter trees that fail validation
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	// MaxDepth bounds how deeply groups nest
+	MaxDepth = 5
+	// MaxConditions bounds the field conditions of a tree
+	MaxConditions = 50
+	// MaxValues bounds the values of an in, not_in, has_any or has_all condition
+	MaxValues = 100
+	// CustomFieldPrefix names a custom field, as in custom.industry
+	CustomFieldPrefix = "custom."
+)
+
+// Op compares a field with the condition's value
+type Op string
+
+const (
+	OpEq  Op = "eq"
+	OpNeq Op = "neq"
+	OpLt  Op = "lt"
+	OpLte Op = "lte"
+	OpGt  Op = "gt"
+	OpGte Op = "gte"
+	// OpIn and OpNotIn take a list of values
+	OpIn    Op = "in"
+	OpNotIn Op = "not_in"
+	// OpContains and OpStartsWith match text case-insensitively
+	OpContains   Op = "contains"
+	OpStartsWith Op = "starts_with"
+	// OpIsNull and OpIsNotNull take no value. A list without values, such
+	// as a contact without tags, is null.
+	OpIsNull    Op = "is_null"
+	OpIsNotNull Op = "is_not_null"
+	// OpHas, OpHasAny and OpHasAll match lists holding the value, any of
+	// the values or all of them
+	OpHas    Op = "has"
+	OpHasAny Op = "has_any"
+	OpHasAll Op = "has_all"
+)
+
+// Type is the data type of a field, which decides the operators and
+// values a condition on it takes
+type Type string
+
+const (
+	TypeText    Type = "text"
+	TypeNumber  Type = "number"
+	TypeBoolean Type = "boolean"
+	// TypeTime values are RFC 3339 timestamps or YYYY-MM-DD dates
+	TypeTime Type = "time"
+	TypeUUID Type = "uuid"
+	// TypeUUIDList is a uuid[] column, such as tags
+	TypeUUIDList Type = "uuid_list"
+)
+
+// typeOps are the operators each type takes
+var typeOps = map[Type][]Op{
+	TypeText:     {OpEq, OpNeq, OpIn, OpNotIn, OpContains, OpStartsWith, OpIsNull, OpIsNotNull},
+	TypeNumber:   {OpEq, OpNeq, OpLt, OpLte, OpGt, OpGte, OpIn, OpNotIn, OpIsNull, OpIsNotNull},
+	TypeBoolean:  {OpEq, OpNeq, OpIsNull, OpIsNotNull},
+	TypeTime:     {OpLt, OpLte, OpGt, OpGte, OpIsNull, OpIsNotNull},
+	TypeUUID:     {OpEq, OpNeq, OpIn, OpNotIn, OpIsNull, OpIsNotNull},
+	TypeUUIDList: {OpHas, OpHasAny, OpHasAll, OpIsNull, OpIsNotNull},
+}
+
+// Field is a filterable column of an entity
+type Field struct {
+	Column string
+	Type   Type
+	// Options are the only values of a text field, when set
+	Options []string
+}
+
+// Schema is what a tree over an entity may filter on
+type Schema struct {
+	Fields map[string]Field
+	// CustomFields is the JSON column of the entity's custom fields, empty
+	// when it has none
+	CustomFields string
+}
+
+// Node is a filter tree: an and or or group of nodes, or a condition on a
+// field. Conditions on custom fields name them with CustomFieldPrefix.
+type Node struct {
+	And   []Node          `json:"and,omitempty"`
+	Or    []Node          `json:"or,omitempty"`
+	Field string          `json:"field,omitempty"`
+	Op    Op              `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// IsEmpty reports whether the tree filters nothing
+func (n *Node) IsEmpty() bool {
+	return n == nil || (n.And == nil && n.Or == nil && n.Field == "" && n.Op == "" && len(n.Value) == 0)
+}
+
+// Condition is a validated tree, rendered to SQL with Render
+type Condition struct {
+	join     string
+	children []*Condition
+	pred     *predicate
+}
+
+// predicate is a condition on one field with its decoded values
+type predicate struct {
+	field  Field
+	op     Op
+	values []interface{}
+	// custom is the definition of a custom field, read from the schema's
+	// CustomFields column
+	custom *customfields.Definition
+	column string
+}
+
+// Compile validates the tree against the schema and the entity's custom
+// field definitions and returns its condition
+func Compile(node Node, schema Schema, defs []customfields.Definition) (*Condition, error) {
+	c := &compiler{schema: schema, defs: defs}
+	return c.node(node, "query", 1)
+}
+
+type compiler struct {
+	schema     Schema
+	defs       []customfields.Definition
+	conditions int
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidQuery, path, fmt.Sprintf(format, args...))
+}
+
+func (c *compiler) node(n Node, path string, depth int) (*Condition, error) {
+	if depth > MaxDepth {
+		return nil, invalid(path, "groups nest deeper than %d levels", MaxDepth)
+	}
+
+	parts := 0
+	for _, set := range []bool{n.And != nil, n.Or != nil, n.Field != ""} {
+		if set {
+			parts++
+		}
+	}
+	if parts != 1 {
+		return nil, invalid(path, "must be exactly one of an and group, an or group or a field condition")
+	}
+
+	if n.Field == "" {
+		join, nodes, name := "AND", n.And, "and"
+		if n.Or != nil {
+			join, nodes, name = "OR", n.Or, "or"
+		}
+		if len(nodes) == 0 {
+			return nil, invalid(path, "%s group is empty", name)
+		}
+		if n.Op != "" || len(n.Value) > 0 {
+			return nil, invalid(path, "groups take no op or value")
+		}
+		group := &Condition{join: join}
+		for i, child := range nodes {
+			compiled, err := c.node(child, fmt.Sprintf("%s.%s[%d]", path, name, i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			group.children = append(group.children, compiled)
+		}
+		return group, nil
+	}
+
+	c.conditions++
+	if c.conditions > MaxConditions {
+		return nil, invalid(path, "at most %d conditions are allowed", MaxConditions)
+	}
+	pred, err := c.predicate(n, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{pred: pred}, nil
+}
+
+func (c *compiler) predicate(n Node, path string) (*predicate, error) {
+	path += "." + n.Field
+	var field Field
+	var def *customfields.Definition
+	if name, ok := strings.CutPrefix(n.Field, CustomFieldPrefix); ok {
+		if c.schema.CustomFields == "" {
+			return nil, invalid(path, "custom fields are not available")
+		}
+		d, ok := customfields.Lookup(c.defs, name)
+		if !ok {
+			return nil, invalid(path, "unknown custom field")
+		}
+		def = &d
+		field = customField(d)
+	} else {
+		var ok bool
+		if field, ok = c.schema.Fields[n.Field]; !ok {
+			return nil, invalid(path, "unknown field")
+		}
+	}
+
+	if !hasOp(typeOps[field.Type], n.Op) {
+		return nil, invalid(path, "unknown op %q for a %s field", n.Op, field.Type)
+	}
+	if n.Op == OpContains || n.Op == OpStartsWith {
+		// Partial matches are not restricted to whole options
+		field.Options = nil
+	}
+	pred := &predicate{field: field, op: n.Op}
+	if def != nil {
+		pred.custom, pred.column = def, c.schema.CustomFields
+	}
+
+	switch n.Op {
+	case OpIsNull, OpIsNotNull:
+		if len(n.Value) > 0 && !bytes.Equal(bytes.TrimSpace(n.Value), []byte("null")) {
+			return nil, invalid(path, "%s takes no value", n.Op)
+		}
+		return pred, nil
+	case OpIn, OpNotIn, OpHasAny, OpHasAll:
+		var raw []json.RawMessage
+		if err := json.Unmarshal(n.Value, &raw); err != nil || len(raw) == 0 {
+			return nil, invalid(path, "%s takes a list of values", n.Op)
+		}
+		if len(raw) > MaxValues {
+			return nil, invalid(path, "%s takes at most %d values", n.Op, MaxValues)
+		}
+		for _, r := range raw {
+			value, err := decodeValue(field, def, r)
+			if err != nil {
+				return nil, invalid(path, "%v", err)
+			}
+			pred.values = append(pred.values, value)
+		}
+		return pred, nil
+	default:
+		if len(n.Value) == 0 {
+			return nil, invalid(path, "%s takes a value", n.Op)
+		}
+		value, err := decodeValue(field, def, n.Value)
+		if err != nil {
+			return nil, invalid(path, "%v", err)
+		}
+		pred.values = []interface{}{value}
+		return pred, nil
+	}
+}
+
+// customField is how a custom field is filtered. Values are stored
+// normalized, so dates and timestamps compare as text; multi_select fields
+// take the list operators.
+func customField(def customfields.Definition) Field {
+	switch def.Type {
+	case customfields.TypeNumber:
+		return Field{Type: TypeNumber}
+	case customfields.TypeBoolean:
+		return Field{Type: TypeBoolean}
+	case customfields.TypeDate, customfields.TypeDateTime:
+		return Field{Type: TypeTime}
+	case customfields.TypeMultiSelect:
+		return Field{Type: TypeUUIDList, Options: def.Options}
+	default:
+		return Field{Type: TypeText, Options: def.Options}
+	}
+}
+
+// expr is the SQL the predicate's field is read with
+func (p *predicate) expr(dialect database.Dialect) string {
+	if p.custom == nil {
+		return p.field.Column
+	}
+	text := "(" + dialect.JSONText(p.column, p.custom.Name) + ")"
+	if p.custom.Type == customfields.TypeNumber {
+		return "CAST(" + text + " AS NUMERIC)"
+	}
+	return text
+}
+
+// decodeValue checks a condition value against the field and returns it
+// in the form it is bound
+func decodeValue(field Field, def *customfields.Definition, raw json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return nil, errors.New("value must not be null")
+	}
+
+	if def != nil {
+		switch def.Type {
+		case customfields.TypeBoolean:
+			b, ok := value.(bool)
+			if !ok {
+				return nil, errors.New("value must be a boolean")
+			}
+			// Booleans are compared as the JSON text they are stored as
+			if b {
+				return "true", nil
+			}
+			return "false", nil
+		case customfields.TypeDate:
+			s, _ := value.(string)
+			d, err := time.Parse(time.DateOnly, s)
+			if err != nil {
+				return nil, errors.New("value must be a date (YYYY-MM-DD)")
+			}
+			return d.Format(time.DateOnly), nil
+		case customfields.TypeDateTime:
+			s, _ := value.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, errors.New("value must be an RFC 3339 timestamp")
+			}
+			return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+		case customfields.TypeMultiSelect:
+			s, ok := value.(string)
+			if !ok || !hasOption(field.Options, s) {
+				return nil, fmt.Errorf("value must be one of %s", strings.Join(field.Options, ", "))
+			}
+			return s, nil
+		}
+	}
+
+	switch field.Type {
+	case TypeText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("value must be a string")
+		}
+		if len(field.Options) > 0 && !hasOption(field.Options, s) {
+			return nil, fmt.Errorf("value must be one of %s", strings.Join(field.Options, ", "))
+		}
+		return s, nil
+	case TypeNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, errors.New("value must be a number")
+		}
+		return n, nil
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("value must be a boolean")
+		}
+		return b, nil
+	case TypeTime:
+		s, _ := value.(string)
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		if d, err := time.Parse(time.DateOnly, s); err == nil {
+			return d, nil
+		}
+		return nil, errors.New("value must be an RFC 3339 timestamp or a date (YYYY-MM-DD)")
+	case TypeUUID, TypeUUIDList:
+		s, _ := value.(string)
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, errors.New("value must be a UUID")
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("unknown field type %q", field.Type)
+}
+
+func hasOp(ops []Op, op Op) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(options []string, value string) bool {
+	for _, o := range options {
+		if o == value {
+			return true
+		}
+	}
+	return false
+}
+
+// comparisons are the SQL operators of the comparing ops
+var comparisons = map[Op]string{
+	OpEq: "=", OpNeq: "<>", OpLt: "<", OpLte: "<=", OpGt: ">", OpGte: ">=",
+}
+
+// Render writes the condition with $n placeholders numbered after args,
+// for the dialect's text and JSON matching, and returns args with the
+// condition's values appended. neq and not_in do not match null values.
+func (c *Condition) Render(dialect database.Dialect, args []interface{}) (string, []interface{}) {
+	dialect = database.DialectOrDefault(dialect)
+	if c.pred == nil {
+		parts := make([]string, len(c.children))
+		for i, child := range c.children {
+			parts[i], args = child.Render(dialect, args)
+		}
+		return "(" + strings.Join(parts, " "+c.join+" ") + ")", args
+	}
+
+	p := c.pred
+	expr := p.expr(dialect)
+	bind := func(value interface{}) int {
+		args = append(args, value)
+		return len(args)
+	}
+	each := func(format, join string) string {
+		parts := make([]string, len(p.values))
+		for i, value := range p.values {
+			parts[i] = fmt.Sprintf(format, bind(value))
+		}
+		return "(" + strings.Join(parts, join) + ")"
+	}
+
+	switch p.op {
+	case OpIsNull, OpIsNotNull:
+		null := expr + " IS NULL"
+		if p.field.Type == TypeUUIDList && p.custom == nil {
+			null = "COALESCE(cardinality(" + expr + "), 0) = 0"
+		}
+		if p.op == OpIsNotNull {
+			null = "NOT (" + null + ")"
+		}
+		return null, args
+	case OpContains, OpStartsWith:
+		mode := database.MatchModeContains
+		if p.op == OpStartsWith {
+			mode = database.MatchModePrefix
+		}
+		return dialect.ILike(expr, bind(database.LikePattern(p.values[0].(string), mode))), args
+	case OpIn:
+		return expr + " IN " + each("$%d", ", "), args
+	case OpNotIn:
+		return expr + " NOT IN " + each("$%d", ", "), args
+	case OpHas, OpHasAny, OpHasAll:
+		join := " OR "
+		if p.op == OpHasAll {
+			join = " AND "
+		}
+		if p.custom != nil {
+			parts := make([]string, len(p.values))
+			for i, value := range p.values {
+				doc, _ := json.Marshal(map[string][]interface{}{p.custom.Name: {value}})
+				parts[i] = dialect.JSONContains(p.column, bind(string(doc)))
+			}
+			return "(" + strings.Join(parts, join) + ")", args
+		}
+		return each("$%d = ANY("+expr+")", join), args
+	default:
+		return fmt.Sprintf("%s %s $%d", expr, comparisons[p.op], bind(p.values[0])), args
+	}
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KevTiv/alieze-erp/pkg/customfields"
+	"github.com/KevTiv/alieze-erp/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = Schema{
+	Fields: map[string]Field{
+		"name":     {Column: "name", Type: TypeText},
+		"status":   {Column: "status", Type: TypeText, Options: []string{"new", "won", "lost"}},
+		"revenue":  {Column: "expected_revenue", Type: TypeNumber},
+		"active":   {Column: "active", Type: TypeBoolean},
+		"created":  {Column: "created_at", Type: TypeTime},
+		"owner_id": {Column: "assigned_to", Type: TypeUUID},
+		"tags":     {Column: "tag_ids", Type: TypeUUIDList},
+	},
+	CustomFields: "custom_fields",
+}
+
+var testFields = []customfields.Definition{
+	{Entity: "lead", Name: "industry", Label: "Industry", Type: customfields.TypeSelect, Options: []string{"retail", "finance"}},
+	{Entity: "lead", Name: "seats", Label: "Seats", Type: customfields.TypeNumber},
+	{Entity: "lead", Name: "renewal", Label: "Renewal", Type: customfields.TypeDateTime},
+	{Entity: "lead", Name: "channels", Label: "Channels", Type: customfields.TypeMultiSelect, Options: []string{"email", "phone"}},
+}
+
+func parse(t *testing.T, tree string) Node {
+	t.Helper()
+	var node Node
+	require.NoError(t, json.Unmarshal([]byte(tree), &node))
+	return node
+}
+
+func TestCompileRendersNestedGroups(t *testing.T) {
+	tag1, tag2 := uuid.New(), uuid.New()
+	node := parse(t, `{"and": [
+		{"field": "name", "op": "contains", "value": "50%"},
+		{"or": [
+			{"field": "status", "op": "in", "value": ["new", "won"]},
+			{"field": "revenue", "op": "gte", "value": 1000}
+		]},
+		{"field": "tags", "op": "has_all", "value": ["`+tag1.String()+`", "`+tag2.String()+`"]},
+		{"field": "owner_id", "op": "is_null"}
+	]}`)
+
+	cond, err := Compile(node, testSchema, nil)
+	require.NoError(t, err)
+
+	// Placeholders continue after the arguments already bound
+	where, args := cond.Render(database.Postgres, []interface{}{"org"})
+	assert.Equal(t, `(name ILIKE $2 ESCAPE '\' AND (status IN ($3, $4) OR expected_revenue >= $5)`+
+		` AND ($6 = ANY(tag_ids) AND $7 = ANY(tag_ids)) AND assigned_to IS NULL)`, where)
+	assert.Equal(t, []interface{}{"org", `%50\%%`, "new", "won", 1000.0, tag1, tag2}, args)
+}
+
+func TestCompileCustomFields(t *testing.T) {
+	node := parse(t, `{"and": [
+		{"field": "custom.industry", "op": "eq", "value": "retail"},
+		{"field": "custom.seats", "op": "gt", "value": 10},
+		{"field": "custom.renewal", "op": "lt", "value": "2025-03-01T09:00:00+02:00"},
+		{"field": "custom.channels", "op": "has_any", "value": ["email", "phone"]},
+		{"field": "tags", "op": "is_not_null"}
+	]}`)
+
+	cond, err := Compile(node, testSchema, testFields)
+	require.NoError(t, err)
+
+	where, args := cond.Render(database.Postgres, nil)
+	assert.Equal(t, `((custom_fields->>'industry') = $1 AND CAST((custom_fields->>'seats') AS NUMERIC) > $2`+
+		` AND (custom_fields->>'renewal') < $3 AND (custom_fields @> $4 OR custom_fields @> $5)`+
+		` AND NOT (COALESCE(cardinality(tag_ids), 0) = 0))`, where)
+	// Timestamps compare with the normalized form custom fields are stored in
+	assert.Equal(t, []interface{}{"retail", 10.0, "2025-03-01T07:00:00Z", `{"channels":["email"]}`, `{"channels":["phone"]}`}, args)
+}
+
+func TestCompileTimeValues(t *testing.T) {
+	cond, err := Compile(parse(t, `{"field": "created", "op": "gte", "value": "2025-01-31"}`), testSchema, nil)
+	require.NoError(t, err)
+
+	where, args := cond.Render(nil, nil)
+	assert.Equal(t, "created_at >= $1", where)
+	assert.Equal(t, []interface{}{time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)}, args)
+}
+
+func TestCompileRejectsInvalidTrees(t *testing.T) {
+	many := make([]string, MaxConditions+1)
+	for i := range many {
+		many[i] = `{"field": "active", "op": "eq", "value": true}`
+	}
+	deep := `{"field": "active", "op": "eq", "value": true}`
+	for range MaxDepth {
+		deep = `{"and": [` + deep + `]}`
+	}
+
+	cases := map[string]string{
+		"empty node":         `{}`,
+		"empty group":        `{"or": []}`,
+		"group and field":    `{"and": [{"field": "active", "op": "eq", "value": true}], "field": "name"}`,
+		"unknown field":      `{"field": "password", "op": "eq", "value": "x"}`,
+		"unknown custom":     `{"field": "custom.budget", "op": "eq", "value": 1}`,
+		"op of another type": `{"field": "revenue", "op": "contains", "value": "1"}`,
+		"wrong value type":   `{"field": "revenue", "op": "eq", "value": "1000"}`,
+		"missing value":      `{"field": "name", "op": "eq"}`,
+		"null value":         `{"field": "name", "op": "eq", "value": null}`,
+		"value on is_null":   `{"field": "name", "op": "is_null", "value": "x"}`,
+		"not a list":         `{"field": "status", "op": "in", "value": "new"}`,
+		"empty list":         `{"field": "status", "op": "in", "value": []}`,
+		"unknown option":     `{"field": "status", "op": "eq", "value": "archived"}`,
+		"bad uuid":           `{"field": "owner_id", "op": "eq", "value": "1 OR 1=1"}`,
+		"bad date":           `{"field": "created", "op": "gt", "value": "yesterday"}`,
+		"custom option":      `{"field": "custom.channels", "op": "has", "value": "fax"}`,
+		"too many":           `{"or": [` + strings.Join(many, ",") + `]}`,
+		"too deep":           deep,
+	}
+	for name, tree := range cases {
+		_, err := Compile(parse(t, tree), testSchema, testFields)
+		assert.ErrorIs(t, err, ErrInvalidQuery, name)
+	}
+
+	// Errors name where in the tree they are
+	_, err := Compile(parse(t, `{"and": [{"field": "active", "op": "eq", "value": true}, {"or": [{"field": "name", "op": "gt", "value": "a"}]}]}`), testSchema, nil)
+	assert.EqualError(t, err, `invalid query: query.and[1].or[0].name: unknown op "gt" for a text field`)
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Headers advertising the caller's allowance, so that integrators can
+// throttle themselves before they are refused
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	// HeaderReset is when the window resets, in Unix seconds
+	HeaderReset = "X-RateLimit-Reset"
+)
+
+// SetHeaders advertises the outcome of a request's Allow call
+func SetHeaders(h http.Header, res Result) {
+	h.Set(HeaderLimit, strconv.Itoa(res.Limit))
+	h.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+	h.Set(HeaderReset, strconv.FormatInt(res.ResetAt.Unix(), 10))
+}
+
+type contextKey struct{}
+
+// WithResult keeps the outcome of a request's Allow call for the handlers
+// reporting it
+func WithResult(ctx context.Context, res Result) context.Context {
+	return context.WithValue(ctx, contextKey{}, res)
+}
+
+// ResultFrom returns the outcome of the request's Allow call, if it was
+// rate limited
+func ResultFrom(ctx context.Context) (Result, bool) {
+	res, ok := ctx.Value(contextKey{}).(Result)
+	return res, ok
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a fixed-window request limiter keyed by caller (organization, IP, ...)
+type Limiter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+// Result describes the outcome of a single Allow call
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// NewLimiter creates a limiter counting requests per window
+func NewLimiter(window time.Duration) *Limiter {
+	return &Limiter{
+		window:  window,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow records a request for key and reports whether it fits within limit
+func (l *Limiter) Allow(key string, limit int) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		l.evictExpired(now)
+		b = &bucket{start: now}
+		l.buckets[key] = b
+	}
+
+	result := Result{Limit: limit, ResetAt: b.start.Add(l.window)}
+	if b.count >= limit {
+		return result
+	}
+
+	b.count++
+	result.Allowed = true
+	result.Remaining = limit - b.count
+	return result
+}
+
+// evictExpired drops buckets whose window has passed so idle keys do not accumulate
+func (l *Limiter) evictExpired(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.start) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllowsUpToLimitPerWindow(t *testing.T) {
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res := l.Allow("org-a", 3)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res := l.Allow("org-a", 3)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, now.Add(time.Minute), res.ResetAt)
+
+	// Keys are counted independently
+	assert.True(t, l.Allow("org-b", 3).Allowed)
+
+	// A new window resets the count
+	now = now.Add(time.Minute)
+	assert.True(t, l.Allow("org-a", 3).Allowed)
+}
+
+func TestLimiterEvictsExpiredBuckets(t *testing.T) {
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("org-a", 10)
+	l.Allow("org-b", 10)
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("org-c", 10)
+
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestSetHeadersAndResultFrom(t *testing.T) {
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+	l.Allow("org-a", 10)
+	res := l.Allow("org-a", 10)
+
+	h := http.Header{}
+	SetHeaders(h, res)
+	assert.Equal(t, "10", h.Get(HeaderLimit))
+	assert.Equal(t, "8", h.Get(HeaderRemaining))
+	assert.Equal(t, strconv.FormatInt(now.Add(time.Minute).Unix(), 10), h.Get(HeaderReset))
+
+	_, ok := ResultFrom(context.Background())
+	assert.False(t, ok)
+	got, ok := ResultFrom(WithResult(context.Background(), res))
+	assert.True(t, ok)
+	assert.Equal(t, res, got)
+}
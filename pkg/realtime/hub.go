@@ -0,0 +1,106 @@
+package realtime
+
+import (
+	"sync"
+)
+
+// DefaultBuffer is the number of events a subscriber may fall behind by
+// before it is disconnected
+const DefaultBuffer = 64
+
+// Event is published to every subscriber of a topic
+type Event struct {
+	Topic string
+	Type  string
+	Data  interface{}
+}
+
+// Hub fans events out to subscribers by topic. It is in-process: subscribers
+// only see events published by the same instance.
+type Hub struct {
+	buffer int
+
+	mu   sync.RWMutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+// Subscription receives the events of one topic on C until it is closed
+type Subscription struct {
+	C <-chan Event
+
+	hub   *Hub
+	topic string
+	ch    chan Event
+	once  sync.Once
+}
+
+// NewHub creates a hub whose subscribers buffer up to buffer events
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Hub{
+		buffer: buffer,
+		subs:   make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts receiving the events of topic. Callers must Close the
+// subscription when done.
+func (h *Hub) Subscribe(topic string) *Subscription {
+	ch := make(chan Event, h.buffer)
+	sub := &Subscription{C: ch, hub: h, topic: topic, ch: ch}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[topic] == nil {
+		h.subs[topic] = make(map[*Subscription]struct{})
+	}
+	h.subs[topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends an event to the current subscribers of topic and returns how
+// many received it. Publish never blocks: a subscriber whose buffer is full
+// is closed, and is expected to reconnect and catch up from history.
+func (h *Hub) Publish(topic, eventType string, data interface{}) int {
+	event := Event{Topic: topic, Type: eventType, Data: data}
+
+	var delivered int
+	var slow []*Subscription
+	h.mu.RLock()
+	for sub := range h.subs[topic] {
+		select {
+		case sub.ch <- event:
+			delivered++
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.Close()
+	}
+	return delivered
+}
+
+// Subscribers returns the number of subscribers of topic
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[topic])
+}
+
+// Close stops the subscription and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		delete(s.hub.subs[s.topic], s)
+		if len(s.hub.subs[s.topic]) == 0 {
+			delete(s.hub.subs, s.topic)
+		}
+		close(s.ch)
+	})
+}
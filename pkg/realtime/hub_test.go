@@ -0,0 +1,63 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublishesToTopicSubscribers(t *testing.T) {
+	hub := NewHub(4)
+	a := hub.Subscribe("route:1")
+	b := hub.Subscribe("route:2")
+	defer a.Close()
+	defer b.Close()
+
+	assert.Equal(t, 1, hub.Publish("route:1", "message", "hello"))
+
+	event := <-a.C
+	assert.Equal(t, "message", event.Type)
+	assert.Equal(t, "hello", event.Data)
+	assert.Empty(t, b.C)
+}
+
+func TestHubClosesSlowSubscribers(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("route:1")
+
+	assert.Equal(t, 1, hub.Publish("route:1", "message", 1))
+	assert.Equal(t, 0, hub.Publish("route:1", "message", 2))
+	assert.Equal(t, 0, hub.Subscribers("route:1"))
+
+	<-sub.C
+	_, ok := <-sub.C
+	assert.False(t, ok, "the channel is closed once drained")
+	sub.Close()
+}
+
+func TestServeSSE(t *testing.T) {
+	hub := NewHub(4)
+	sub := hub.Subscribe("route:1")
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan error)
+	go func() { done <- ServeSSE(rec, req, sub, time.Hour) }()
+
+	hub.Publish("route:1", "message", map[string]string{"body": "hi"})
+	// Closing the subscription ends the stream after pending events are written
+	sub.Close()
+	require.NoError(t, <-done)
+	cancel()
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "event: message\ndata: {\"body\":\"hi\"}\n\n", rec.Body.String())
+}
@@ -0,0 +1,63 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultKeepAlive is how often an idle stream sends a comment so proxies
+// keep it open and dead clients are noticed
+const DefaultKeepAlive = 25 * time.Second
+
+// ServeSSE streams the subscription's events to the client as server-sent
+// events, using the event type as the SSE event name and the JSON encoded
+// data as its payload. It returns when the client disconnects or the
+// subscription is closed; it does not close the subscription.
+func ServeSSE(w http.ResponseWriter, r *http.Request, sub *Subscription, keepAlive time.Duration) error {
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server's write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return fmt.Errorf("streaming is not supported: %w", err)
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+	}
+}
@@ -4,19 +4,26 @@ import (
 	"database/sql"
 	"log/slog"
 
+	"github.com/KevTiv/alieze-erp/pkg/apiversion"
+	"github.com/KevTiv/alieze-erp/pkg/database"
 	"github.com/KevTiv/alieze-erp/pkg/events"
 	"github.com/KevTiv/alieze-erp/pkg/policy"
 	"github.com/KevTiv/alieze-erp/pkg/rules"
+	"github.com/KevTiv/alieze-erp/pkg/scheduler"
 	"github.com/KevTiv/alieze-erp/pkg/workflow"
 )
 
 // Dependencies contains the shared dependencies for all modules
 type Dependencies struct {
 	DB                  *sql.DB
+	PermissionDB        *database.PermissionDB // DB with policy checks and audit logging on each query
+	Dialect             database.Dialect       // SQL dialect of DB; nil means PostgreSQL
 	EventBus            *events.Bus
 	RuleEngine          *rules.RuleEngine
 	PolicyEngine        *policy.Engine
 	StateMachineFactory *workflow.StateMachineFactory
+	Scheduler           *scheduler.Coordinator // Runs periodic jobs once across instances; nil runs them on every instance
+	APIVersions         *apiversion.Catalog    // Tracks versioned endpoints and their deprecations; nil serves them untracked
 	Logger              *slog.Logger
 	ProductRepo         interface{} // Product repository for inventory module
 	AuthService         interface{} // Auth service for quality control
@@ -1,6 +1,10 @@
 package registry
 
-import "context"
+import (
+	"context"
+
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+)
 
 // Module represents a modular component of the ERP system
 type Module interface {
@@ -10,3 +14,8 @@ type Module interface {
 	RegisterEventHandlers(bus interface{})
 	Health() error
 }
+
+// EnumProvider is implemented by modules that list enumerations to clients
+type EnumProvider interface {
+	Enums() []enums.Enum
+}
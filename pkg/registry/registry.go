@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -54,6 +56,17 @@ func (r *Registry) RegisterAllRoutes(router *httprouter.Router) {
 	}
 }
 
+// Enums returns the enumerations of all modules that provide them
+func (r *Registry) Enums() []enums.Enum {
+	var all []enums.Enum
+	for _, module := range r.modules {
+		if provider, ok := module.(EnumProvider); ok {
+			all = append(all, provider.Enums()...)
+		}
+	}
+	return all
+}
+
 // RegisterAllEventHandlers registers event handlers for all modules
 func (r *Registry) RegisterAllEventHandlers(bus interface{}) {
 	for _, module := range r.modules {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/KevTiv/alieze-erp/pkg/enums"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -104,3 +106,23 @@ func TestRegistryInitError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to init failing")
 }
+
+// MockEnumModule is a test module that lists enumerations
+type MockEnumModule struct {
+	MockModule
+	enums []enums.Enum
+}
+
+func (m *MockEnumModule) Enums() []enums.Enum {
+	return m.enums
+}
+
+func TestRegistryEnums(t *testing.T) {
+	registry := NewRegistry(Dependencies{})
+	priority := enums.Enum{Name: "crm.lead_priority"}
+
+	registry.Register(&MockModule{name: "sales"})
+	registry.Register(&MockEnumModule{MockModule: MockModule{name: "crm"}, enums: []enums.Enum{priority}})
+
+	assert.Equal(t, []enums.Enum{priority}, registry.Enums())
+}
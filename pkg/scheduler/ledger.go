@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Run statuses recorded in the ledger
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// PostgresLedger records runs in the scheduled_job_runs table
+type PostgresLedger struct {
+	db         *sql.DB
+	instanceID string
+	now        func() time.Time
+}
+
+var _ Ledger = &PostgresLedger{}
+
+func NewPostgresLedger(db *sql.DB, instanceID string) *PostgresLedger {
+	return &PostgresLedger{db: db, instanceID: instanceID, now: time.Now}
+}
+
+// Begin starts an attempt at a slot unless it completed or used up its
+// attempts
+func (l *PostgresLedger) Begin(ctx context.Context, name string, slot time.Time) (int, bool, error) {
+	var attempt int
+	err := l.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_job_runs (job_name, slot, status, attempts, instance_id, started_at)
+		VALUES ($1, $2, $3, 1, $4, $5)
+		ON CONFLICT (job_name, slot) DO UPDATE SET
+			status = EXCLUDED.status,
+			attempts = scheduled_job_runs.attempts + 1,
+			instance_id = EXCLUDED.instance_id,
+			started_at = EXCLUDED.started_at,
+			finished_at = NULL,
+			error = NULL
+		WHERE scheduled_job_runs.status <> $6 AND scheduled_job_runs.attempts < $7
+		RETURNING attempts
+	`, name, slot, StatusRunning, l.instanceID, l.now(), StatusCompleted, MaxAttempts).Scan(&attempt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, true, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin run of %s: %w", name, err)
+	}
+	return attempt, false, nil
+}
+
+// Finish records the outcome of the attempt at a slot. Completing a slot
+// prunes the job's runs older than LedgerRetention.
+func (l *PostgresLedger) Finish(ctx context.Context, name string, slot time.Time, runErr error) error {
+	status := StatusCompleted
+	var message *string
+	if runErr != nil {
+		status = StatusFailed
+		msg := runErr.Error()
+		message = &msg
+	}
+
+	now := l.now()
+	if _, err := l.db.ExecContext(ctx, `
+		UPDATE scheduled_job_runs
+		SET status = $3, error = $4, finished_at = $5
+		WHERE job_name = $1 AND slot = $2
+	`, name, slot, status, message, now); err != nil {
+		return fmt.Errorf("failed to finish run of %s: %w", name, err)
+	}
+
+	if runErr == nil {
+		if _, err := l.db.ExecContext(ctx, `
+			DELETE FROM scheduled_job_runs WHERE job_name = $1 AND slot < $2
+		`, name, now.Add(-LedgerRetention)); err != nil {
+			return fmt.Errorf("failed to prune runs of %s: %w", name, err)
+		}
+	}
+	return nil
+}
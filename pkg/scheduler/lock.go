@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LockKey maps a lock name onto a Postgres advisory lock key
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLocker takes named Postgres session advisory locks. Each lock is
+// held on its own connection, so it is released when that connection dies.
+type AdvisoryLocker struct {
+	db *sql.DB
+}
+
+var _ Locker = &AdvisoryLocker{}
+
+func NewAdvisoryLocker(db *sql.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+// WithLock runs fn while holding the named lock, reporting false without
+// running fn when another session holds it
+func (l *AdvisoryLocker) WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	key := LockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, key)
+
+	return true, fn(ctx)
+}
+
+// Elector campaigns for a named leadership held as a Postgres session
+// advisory lock. The leader keeps its connection open and checks on every
+// campaign that the lock is still held; when the session dies the lock is
+// released and another instance takes over.
+type Elector struct {
+	db       *sql.DB
+	name     string
+	key      int64
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu     sync.RWMutex
+	conn   *sql.Conn
+	leader bool
+}
+
+var _ Leadership = &Elector{}
+
+func NewElector(db *sql.DB, name string, interval time.Duration, logger *slog.Logger) *Elector {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Elector{
+		db:       db,
+		name:     name,
+		key:      LockKey(name),
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// IsLeader reports whether this instance holds the leadership
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Start campaigns every interval until ctx is cancelled, then resigns
+func (e *Elector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		e.campaign(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				e.resign()
+				return
+			case <-ticker.C:
+				e.campaign(ctx)
+			}
+		}
+	}()
+}
+
+// campaign takes the leadership when it is free, or confirms it is still
+// held when this instance leads
+func (e *Elector) campaign(ctx context.Context) {
+	e.mu.RLock()
+	conn := e.conn
+	e.mu.RUnlock()
+
+	if conn != nil {
+		var held bool
+		err := conn.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM pg_locks
+				WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+				  AND ((classid::bigint << 32) | objid::bigint) = $1
+			)
+		`, e.key).Scan(&held)
+		if err == nil && held {
+			return
+		}
+		e.logger.Warn("Lost scheduler leadership", "lock", e.name, "error", err)
+		e.mu.Lock()
+		e.conn = nil
+		e.leader = false
+		e.mu.Unlock()
+		conn.Close()
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		e.logger.Error("Failed to campaign for scheduler leadership", "lock", e.name, "error", err)
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, e.key).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			e.logger.Error("Failed to campaign for scheduler leadership", "lock", e.name, "error", err)
+		}
+		conn.Close()
+		return
+	}
+
+	e.mu.Lock()
+	e.conn = conn
+	e.leader = true
+	e.mu.Unlock()
+	e.logger.Info("Took scheduler leadership", "lock", e.name)
+}
+
+// resign releases the leadership so another instance takes over without
+// waiting for this session to time out
+func (e *Elector) resign() {
+	e.mu.Lock()
+	conn := e.conn
+	e.conn = nil
+	e.leader = false
+	e.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, e.key)
+	conn.Close()
+}
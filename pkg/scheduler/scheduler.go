@@ -0,0 +1,262 @@
+// Package scheduler runs periodic background jobs once per period across
+// every instance of the server.
+//
+// One instance is elected leader through a Postgres advisory lock and only
+// the leader starts jobs. Each run additionally holds a per-job advisory lock,
+// so a leader that lost its session cannot overlap the run of its successor,
+// and is recorded in a ledger keyed by job name and period slot, so a slot
+// that completed is never run again, even after a failover.
+//
+// Jobs scheduled through a Coordinator must follow these rules, which the
+// coordinator enforces where it can:
+//
+//   - Job names are unique; Every rejects a name scheduled twice.
+//   - A run covers exactly one slot, the start of its period. Work that
+//     depends on the clock should be computed from Run.Slot, so a retried or
+//     late run produces the same result as an on-time one.
+//   - A run may be retried for the same slot after a failure, up to
+//     MaxAttempts, so it must be safe to run again after partially succeeding:
+//     use upserts, conditional updates or claims, never blind inserts.
+//   - A run must finish within its interval; its context is cancelled at the
+//     end of the period.
+//
+// Work claimed row by row with FOR UPDATE SKIP LOCKED does not need a
+// coordinator; every instance can poll for it.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAttempts is how many times a failing slot is attempted
+	MaxAttempts = 3
+	// LedgerRetention is how long completed runs stay in the ledger
+	LedgerRetention = 7 * 24 * time.Hour
+
+	leaderLockName   = "scheduler:leader"
+	campaignInterval = 10 * time.Second
+	minCheckInterval = time.Second
+	maxCheckInterval = time.Minute
+)
+
+// ErrInvalid is returned when a job cannot be scheduled
+var ErrInvalid = errors.New("invalid schedule")
+
+// Run describes one run of a scheduled job
+type Run struct {
+	Name    string
+	Slot    time.Time
+	Attempt int
+}
+
+// Key identifies the run's slot; runs of the same job and slot share it
+func (r Run) Key() string {
+	return r.Name + "@" + r.Slot.UTC().Format(time.RFC3339)
+}
+
+// JobFunc performs one run of a scheduled job
+type JobFunc func(ctx context.Context, run Run) error
+
+// Leadership reports whether this instance currently starts scheduled jobs
+type Leadership interface {
+	IsLeader() bool
+}
+
+// Locker runs fn while holding a named lock shared by all instances
+type Locker interface {
+	// WithLock reports false without running fn when another holder has
+	// the lock
+	WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error)
+}
+
+// Ledger records which slots of each job have run
+type Ledger interface {
+	// Begin starts an attempt at a slot, reporting done when the slot has
+	// completed or used up its attempts
+	Begin(ctx context.Context, name string, slot time.Time) (attempt int, done bool, err error)
+	// Finish records the outcome of the attempt at a slot
+	Finish(ctx context.Context, name string, slot time.Time, runErr error) error
+}
+
+// Coordinator schedules periodic jobs so that each period runs once across
+// all instances. A nil Coordinator runs jobs on every instance, which suits
+// single-instance deployments and tests.
+type Coordinator struct {
+	leader Leadership
+	locks  Locker
+	ledger Ledger
+	logger *slog.Logger
+	now    func() time.Time
+
+	elector *Elector
+
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// NewCoordinator creates a coordinator electing its leader and recording runs
+// in db. Start must be called for this instance to take part in the election.
+func NewCoordinator(db *sql.DB, logger *slog.Logger) *Coordinator {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	locks := NewAdvisoryLocker(db)
+	elector := NewElector(db, leaderLockName, campaignInterval, logger)
+	c := NewCoordinatorWith(elector, locks, NewPostgresLedger(db, instanceID()), logger)
+	c.elector = elector
+	return c
+}
+
+// NewCoordinatorWith creates a coordinator from its parts
+func NewCoordinatorWith(leader Leadership, locks Locker, ledger Ledger, logger *slog.Logger) *Coordinator {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Coordinator{
+		leader: leader,
+		locks:  locks,
+		ledger: ledger,
+		logger: logger,
+		now:    time.Now,
+		names:  make(map[string]bool),
+	}
+}
+
+// Start campaigns for leadership until ctx is cancelled
+func (c *Coordinator) Start(ctx context.Context) {
+	if c != nil && c.elector != nil {
+		c.elector.Start(ctx)
+	}
+}
+
+// IsLeader reports whether this instance currently starts scheduled jobs
+func (c *Coordinator) IsLeader() bool {
+	return c == nil || c.leader.IsLeader()
+}
+
+// Every runs fn once per interval, aligned to multiples of interval since
+// the zero time, until ctx is cancelled
+func (c *Coordinator) Every(ctx context.Context, name string, interval time.Duration, fn JobFunc) error {
+	if name == "" {
+		return fmt.Errorf("%w: job name is required", ErrInvalid)
+	}
+	if interval < time.Second {
+		return fmt.Errorf("%w: interval of %s must be at least one second", ErrInvalid, name)
+	}
+	if c != nil {
+		c.mu.Lock()
+		taken := c.names[name]
+		c.names[name] = true
+		c.mu.Unlock()
+		if taken {
+			return fmt.Errorf("%w: job %s is already scheduled", ErrInvalid, name)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkInterval(interval))
+		defer ticker.Stop()
+
+		// A coordinated job catches up on the current slot at startup, since
+		// the ledger knows whether it already ran. An uncoordinated one
+		// cannot tell, so it waits for the next slot.
+		var settled time.Time
+		if c == nil {
+			settled = time.Now().Truncate(interval)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				slot := c.clock().Truncate(interval)
+				if !slot.After(settled) {
+					continue
+				}
+				if c.tick(ctx, name, slot, interval, fn) {
+					settled = slot
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// tick attempts the slot and reports whether it is settled, either run to
+// completion or given up on
+func (c *Coordinator) tick(ctx context.Context, name string, slot time.Time, interval time.Duration, fn JobFunc) bool {
+	runCtx, cancel := context.WithDeadline(ctx, slot.Add(interval))
+	defer cancel()
+
+	if c == nil {
+		fn(runCtx, Run{Name: name, Slot: slot, Attempt: 1})
+		return true
+	}
+	if !c.leader.IsLeader() {
+		return false
+	}
+
+	settled := false
+	ran, err := c.locks.WithLock(runCtx, "scheduler:job:"+name, func(ctx context.Context) error {
+		attempt, done, err := c.ledger.Begin(ctx, name, slot)
+		if err != nil {
+			return err
+		}
+		if done {
+			settled = true
+			return nil
+		}
+
+		runErr := fn(ctx, Run{Name: name, Slot: slot, Attempt: attempt})
+		if err := c.ledger.Finish(context.WithoutCancel(ctx), name, slot, runErr); err != nil {
+			return err
+		}
+		settled = runErr == nil || attempt >= MaxAttempts
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Scheduled job coordination failed", "job", name, "slot", slot, "error", err)
+		return false
+	}
+	if !ran {
+		c.logger.Debug("Scheduled job is running elsewhere", "job", name, "slot", slot)
+	}
+	return settled
+}
+
+func (c *Coordinator) clock() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// checkInterval is how often a job's slot is checked: often enough to start
+// a run shortly after its slot opens and to retry a failed one within the
+// period
+func checkInterval(interval time.Duration) time.Duration {
+	check := interval / 10
+	if check < minCheckInterval {
+		check = minCheckInterval
+	}
+	if check > maxCheckInterval {
+		check = maxCheckInterval
+	}
+	return check
+}
+
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLeader struct{ leader bool }
+
+func (f *fakeLeader) IsLeader() bool { return f.leader }
+
+type fakeLocker struct{ held map[string]bool }
+
+func (f *fakeLocker) WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	if f.held[name] {
+		return false, nil
+	}
+	return true, fn(ctx)
+}
+
+type fakeLedger struct {
+	attempts map[string]int
+	done     map[string]bool
+}
+
+func newFakeLedger() *fakeLedger {
+	return &fakeLedger{attempts: map[string]int{}, done: map[string]bool{}}
+}
+
+func (f *fakeLedger) Begin(ctx context.Context, name string, slot time.Time) (int, bool, error) {
+	key := Run{Name: name, Slot: slot}.Key()
+	if f.done[key] || f.attempts[key] >= MaxAttempts {
+		return 0, true, nil
+	}
+	f.attempts[key]++
+	return f.attempts[key], false, nil
+}
+
+func (f *fakeLedger) Finish(ctx context.Context, name string, slot time.Time, runErr error) error {
+	if runErr == nil {
+		f.done[Run{Name: name, Slot: slot}.Key()] = true
+	}
+	return nil
+}
+
+func TestTickRunsEachSlotOnceAcrossInstances(t *testing.T) {
+	ledger := newFakeLedger()
+	locks := &fakeLocker{held: map[string]bool{}}
+	first := NewCoordinatorWith(&fakeLeader{leader: true}, locks, ledger, nil)
+	// A second leader after failover shares the ledger
+	second := NewCoordinatorWith(&fakeLeader{leader: true}, locks, ledger, nil)
+
+	slot := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	var runs []Run
+	fn := func(ctx context.Context, run Run) error {
+		runs = append(runs, run)
+		return nil
+	}
+
+	assert.True(t, first.tick(context.Background(), "job", slot, time.Hour, fn))
+	assert.True(t, second.tick(context.Background(), "job", slot, time.Hour, fn))
+	require.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Attempt)
+	assert.Equal(t, "job@2025-02-01T12:00:00Z", runs[0].Key())
+
+	// The next slot runs again
+	assert.True(t, second.tick(context.Background(), "job", slot.Add(time.Hour), time.Hour, fn))
+	assert.Len(t, runs, 2)
+}
+
+func TestTickSkipsFollowersAndHeldLocks(t *testing.T) {
+	ledger := newFakeLedger()
+	called := false
+	fn := func(ctx context.Context, run Run) error {
+		called = true
+		return nil
+	}
+	slot := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	follower := NewCoordinatorWith(&fakeLeader{}, &fakeLocker{}, ledger, nil)
+	assert.False(t, follower.tick(context.Background(), "job", slot, time.Hour, fn))
+
+	// A deposed leader still running the job holds its lock
+	locked := NewCoordinatorWith(&fakeLeader{leader: true}, &fakeLocker{held: map[string]bool{"scheduler:job:job": true}}, ledger, nil)
+	assert.False(t, locked.tick(context.Background(), "job", slot, time.Hour, fn))
+
+	assert.False(t, called)
+}
+
+func TestTickRetriesFailedSlotUpToMaxAttempts(t *testing.T) {
+	c := NewCoordinatorWith(&fakeLeader{leader: true}, &fakeLocker{}, newFakeLedger(), nil)
+	slot := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	var attempts []int
+	fn := func(ctx context.Context, run Run) error {
+		attempts = append(attempts, run.Attempt)
+		return errors.New("boom")
+	}
+
+	for i := 1; i < MaxAttempts; i++ {
+		assert.False(t, c.tick(context.Background(), "job", slot, time.Hour, fn))
+	}
+	// The last attempt settles the slot even though it failed
+	assert.True(t, c.tick(context.Background(), "job", slot, time.Hour, fn))
+	assert.True(t, c.tick(context.Background(), "job", slot, time.Hour, fn))
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+func TestTickBoundsRunToItsPeriod(t *testing.T) {
+	c := NewCoordinatorWith(&fakeLeader{leader: true}, &fakeLocker{}, newFakeLedger(), nil)
+	slot := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	c.tick(context.Background(), "job", slot, time.Hour, func(ctx context.Context, run Run) error {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, slot.Add(time.Hour), deadline)
+		return nil
+	})
+}
+
+func TestEveryRejectsDuplicateNames(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewCoordinatorWith(&fakeLeader{}, &fakeLocker{}, newFakeLedger(), nil)
+	noop := func(ctx context.Context, run Run) error { return nil }
+
+	require.NoError(t, c.Every(ctx, "job", time.Hour, noop))
+	assert.ErrorIs(t, c.Every(ctx, "job", time.Hour, noop), ErrInvalid)
+	assert.ErrorIs(t, c.Every(ctx, "other", time.Millisecond, noop), ErrInvalid)
+}
+
+func TestCheckIntervalIsClamped(t *testing.T) {
+	assert.Equal(t, 6*time.Second, checkInterval(time.Minute))
+	assert.Equal(t, time.Second, checkInterval(2*time.Second))
+	assert.Equal(t, time.Minute, checkInterval(24*time.Hour))
+}
+
+func TestPostgresLedgerBegin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ledger := NewPostgresLedger(db, "host:1")
+	slot := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	query := regexp.QuoteMeta("INSERT INTO scheduled_job_runs")
+
+	mock.ExpectQuery(query).
+		WithArgs("job", slot, StatusRunning, "host:1", sqlmock.AnyArg(), StatusCompleted, MaxAttempts).
+		WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(2))
+	attempt, done, err := ledger.Begin(context.Background(), "job", slot)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, 2, attempt)
+
+	// No row back means the slot completed or ran out of attempts
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+	_, done, err = ledger.Begin(context.Background(), "job", slot)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,572 @@
+// Package sftp is a minimal SFTP (version 3) client for exchanging files
+// with customers' servers. It uploads, downloads, lists, renames, removes
+// and stats files, and creates directories.
+package sftp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Packet types of the SFTP version 3 protocol
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpOpen    = 3
+	fxpClose   = 4
+	fxpRead    = 5
+	fxpWrite   = 6
+	fxpOpenDir = 11
+	fxpReadDir = 12
+	fxpRemove  = 13
+	fxpMkdir   = 14
+	fxpStat    = 17
+	fxpRename  = 18
+	fxpStatus  = 101
+	fxpHandle  = 102
+	fxpData    = 103
+	fxpName    = 104
+	fxpAttrs   = 105
+)
+
+const (
+	// Open flags
+	fxfRead  = 0x01
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+
+	// Status codes
+	fxOK         = 0
+	fxEOF        = 1
+	fxNoSuchFile = 2
+
+	// Attribute flags, in the order of their fields
+	attrSize        = 0x01
+	attrUIDGID      = 0x02
+	attrPermissions = 0x04
+	attrACModTime   = 0x08
+	attrExtended    = 0x80000000
+
+	// modeDir is the directory type of the permissions attribute
+	modeDir = 0o040000
+	// chunkSize is the data sent per write or asked for per read request
+	chunkSize = 32 * 1024
+	// maxPacket bounds the responses accepted from servers
+	maxPacket = 256 * 1024
+)
+
+// ErrNotExist is returned for paths that do not exist on the server
+var ErrNotExist = errors.New("no such file")
+
+// StatusError is a failure status returned by the server
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: request failed with code %d", e.Code)
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotExist && e.Code == fxNoSuchFile
+}
+
+// FileInfo is what Stat and ReadDir report about a path. Name is only set
+// by ReadDir; ModTime is zero when the server does not report it.
+type FileInfo struct {
+	Name    string
+	Size    int64
+	IsDir   bool
+	ModTime time.Time
+}
+
+// Client speaks SFTP over a pair of streams, usually the sftp subsystem of
+// an SSH session. Requests are sent one at a time.
+type Client struct {
+	mu     sync.Mutex
+	w      io.Writer
+	r      io.Reader
+	nextID uint32
+	closer func() error
+}
+
+// NewClient starts an SFTP session over r and w
+func NewClient(r io.Reader, w io.Writer) (*Client, error) {
+	c := &Client{w: w, r: r}
+	if err := c.send(fxpInit, uint32(3)); err != nil {
+		return nil, err
+	}
+	typ, _, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("sftp: unexpected packet %d during handshake", typ)
+	}
+	return c, nil
+}
+
+// Dial connects to the SSH server at addr and starts its sftp subsystem.
+// config must verify the server's host key.
+func Dial(ctx context.Context, addr string, config *ssh.ClientConfig) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	session, err := sshClient.NewSession()
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("sftp subsystem unavailable: %w", err)
+	}
+
+	c, err := NewClient(r, w)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	c.closer = func() error {
+		session.Close()
+		return sshClient.Close()
+	}
+	return c, nil
+}
+
+// Close ends the session and its SSH connection
+func (c *Client) Close() error {
+	if c.closer != nil {
+		return c.closer()
+	}
+	return nil
+}
+
+// Create writes the content of r to path, replacing any file there, and
+// returns the bytes written
+func (c *Client) Create(path string, r io.Reader) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	handle, err := c.open(path, fxfWrite|fxfCreat|fxfTrunc)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if err := c.status(fxpWrite, handle, uint64(offset), buf[:n]); err != nil {
+				c.status(fxpClose, handle)
+				return offset, err
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			c.status(fxpClose, handle)
+			return offset, readErr
+		}
+	}
+	return offset, c.status(fxpClose, handle)
+}
+
+// Read writes the content of the file at path to w and returns the bytes
+// written
+func (c *Client) Read(path string, w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	handle, err := c.open(path, fxfRead)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for {
+		id, err := c.request(fxpRead, handle, uint64(offset), uint32(chunkSize))
+		if err != nil {
+			return offset, err
+		}
+		typ, payload, err := c.response(id)
+		if err != nil {
+			return offset, err
+		}
+		if typ == fxpStatus {
+			if err := parseStatus(payload); isEOF(err) {
+				break
+			} else if err != nil {
+				c.status(fxpClose, handle)
+				return offset, err
+			}
+			continue
+		}
+		if typ != fxpData {
+			c.status(fxpClose, handle)
+			return offset, fmt.Errorf("sftp: unexpected packet %d", typ)
+		}
+		data, _, ok := readString(payload)
+		if !ok {
+			c.status(fxpClose, handle)
+			return offset, errors.New("sftp: malformed data")
+		}
+		n, err := w.Write([]byte(data))
+		offset += int64(n)
+		if err != nil {
+			c.status(fxpClose, handle)
+			return offset, err
+		}
+	}
+	return offset, c.status(fxpClose, handle)
+}
+
+// ReadDir lists the directory at path, without its . and .. entries
+func (c *Client) ReadDir(path string) ([]FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	handle, err := c.openHandle(fxpOpenDir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FileInfo
+	for {
+		id, err := c.request(fxpReadDir, handle)
+		if err != nil {
+			return nil, err
+		}
+		typ, payload, err := c.response(id)
+		if err != nil {
+			return nil, err
+		}
+		if typ == fxpStatus {
+			if err := parseStatus(payload); isEOF(err) {
+				break
+			} else if err != nil {
+				c.status(fxpClose, handle)
+				return nil, err
+			}
+			continue
+		}
+		if typ != fxpName {
+			c.status(fxpClose, handle)
+			return nil, fmt.Errorf("sftp: unexpected packet %d", typ)
+		}
+		names, err := parseNames(payload)
+		if err != nil {
+			c.status(fxpClose, handle)
+			return nil, err
+		}
+		for _, entry := range names {
+			if entry.Name != "." && entry.Name != ".." {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, c.status(fxpClose, handle)
+}
+
+// Mkdir creates the directory at path
+func (c *Client) Mkdir(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status(fxpMkdir, path, uint32(0))
+}
+
+// Rename moves oldpath to newpath. Version 3 servers refuse to replace an
+// existing newpath; remove it first.
+func (c *Client) Rename(oldpath, newpath string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status(fxpRename, oldpath, newpath)
+}
+
+// Remove deletes the file at path
+func (c *Client) Remove(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status(fxpRemove, path)
+}
+
+// Stat returns the size and type of the file or directory at path
+func (c *Client) Stat(path string) (*FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.request(fxpStat, path)
+	if err != nil {
+		return nil, err
+	}
+	typ, payload, err := c.response(id)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case fxpAttrs:
+		info, _, err := parseAttrs(payload)
+		return info, err
+	case fxpStatus:
+		return nil, parseStatus(payload)
+	default:
+		return nil, fmt.Errorf("sftp: unexpected packet %d", typ)
+	}
+}
+
+// open opens path and returns its handle
+func (c *Client) open(path string, flags uint32) (string, error) {
+	return c.openHandle(fxpOpen, path, flags, uint32(0))
+}
+
+// openHandle sends a request answered with a handle and returns the handle
+func (c *Client) openHandle(typ byte, args ...interface{}) (string, error) {
+	id, err := c.request(typ, args...)
+	if err != nil {
+		return "", err
+	}
+	typ, payload, err := c.response(id)
+	if err != nil {
+		return "", err
+	}
+	switch typ {
+	case fxpHandle:
+		handle, _, ok := readString(payload)
+		if !ok {
+			return "", errors.New("sftp: malformed handle")
+		}
+		return handle, nil
+	case fxpStatus:
+		return "", parseStatus(payload)
+	default:
+		return "", fmt.Errorf("sftp: unexpected packet %d", typ)
+	}
+}
+
+// status sends a request answered with a status and returns its error
+func (c *Client) status(typ byte, args ...interface{}) error {
+	id, err := c.request(typ, args...)
+	if err != nil {
+		return err
+	}
+	respType, payload, err := c.response(id)
+	if err != nil {
+		return err
+	}
+	if respType != fxpStatus {
+		return fmt.Errorf("sftp: unexpected packet %d", respType)
+	}
+	return parseStatus(payload)
+}
+
+// request sends a packet with a new request ID and returns the ID
+func (c *Client) request(typ byte, args ...interface{}) (uint32, error) {
+	c.nextID++
+	id := c.nextID
+	return id, c.send(typ, append([]interface{}{id}, args...)...)
+}
+
+// response reads the response to request id
+func (c *Client) response(id uint32) (byte, []byte, error) {
+	typ, payload, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(payload) < 4 || binary.BigEndian.Uint32(payload) != id {
+		return 0, nil, errors.New("sftp: response to another request")
+	}
+	return typ, payload[4:], nil
+}
+
+// send writes a packet of the fields, which are uint32, uint64, string or
+// []byte
+func (c *Client) send(typ byte, fields ...interface{}) error {
+	packet := []byte{0, 0, 0, 0, typ}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			packet = binary.BigEndian.AppendUint32(packet, v)
+		case uint64:
+			packet = binary.BigEndian.AppendUint64(packet, v)
+		case string:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		case []byte:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		default:
+			return fmt.Errorf("sftp: cannot encode %T", field)
+		}
+	}
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	_, err := c.w.Write(packet)
+	return err
+}
+
+// recv reads a packet and returns its type and payload
+func (c *Client) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp: connection lost: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("sftp: connection lost: %w", err)
+	}
+	return header[4], payload, nil
+}
+
+func parseStatus(payload []byte) error {
+	if len(payload) < 4 {
+		return errors.New("sftp: malformed status")
+	}
+	code := binary.BigEndian.Uint32(payload)
+	if code == fxOK {
+		return nil
+	}
+	message, _, _ := readString(payload[4:])
+	return &StatusError{Code: code, Message: message}
+}
+
+// isEOF reports whether err is the status ending reads and listings
+func isEOF(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == fxEOF
+}
+
+// parseAttrs reads an attributes structure and returns the rest
+func parseAttrs(payload []byte) (*FileInfo, []byte, error) {
+	malformed := errors.New("sftp: malformed attributes")
+	if len(payload) < 4 {
+		return nil, nil, malformed
+	}
+	flags := binary.BigEndian.Uint32(payload)
+	payload = payload[4:]
+
+	var info FileInfo
+	if flags&attrSize != 0 {
+		if len(payload) < 8 {
+			return nil, nil, malformed
+		}
+		info.Size = int64(binary.BigEndian.Uint64(payload))
+		payload = payload[8:]
+	}
+	if flags&attrUIDGID != 0 {
+		if len(payload) < 8 {
+			return nil, nil, malformed
+		}
+		payload = payload[8:]
+	}
+	if flags&attrPermissions != 0 {
+		if len(payload) < 4 {
+			return nil, nil, malformed
+		}
+		info.IsDir = binary.BigEndian.Uint32(payload)&0o170000 == modeDir
+		payload = payload[4:]
+	}
+	if flags&attrACModTime != 0 {
+		if len(payload) < 8 {
+			return nil, nil, malformed
+		}
+		info.ModTime = time.Unix(int64(binary.BigEndian.Uint32(payload[4:])), 0)
+		payload = payload[8:]
+	}
+	if flags&attrExtended != 0 {
+		if len(payload) < 4 {
+			return nil, nil, malformed
+		}
+		count := binary.BigEndian.Uint32(payload)
+		payload = payload[4:]
+		for i := uint32(0); i < count*2; i++ {
+			var ok bool
+			if _, payload, ok = readString(payload); !ok {
+				return nil, nil, malformed
+			}
+		}
+	}
+	return &info, payload, nil
+}
+
+// parseNames reads the entries of a name response
+func parseNames(payload []byte) ([]FileInfo, error) {
+	malformed := errors.New("sftp: malformed name list")
+	if len(payload) < 4 {
+		return nil, malformed
+	}
+	count := binary.BigEndian.Uint32(payload)
+	payload = payload[4:]
+
+	var entries []FileInfo
+	for i := uint32(0); i < count; i++ {
+		name, rest, ok := readString(payload)
+		if !ok {
+			return nil, malformed
+		}
+		// The long name is ls -l output meant for people
+		if _, rest, ok = readString(rest); !ok {
+			return nil, malformed
+		}
+		info, rest, err := parseAttrs(rest)
+		if err != nil {
+			return nil, err
+		}
+		info.Name = name
+		entries = append(entries, *info)
+		payload = rest
+	}
+	return entries, nil
+}
+
+// readString reads a length-prefixed string and returns the rest
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 4 {
+		return "", nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", nil, false
+	}
+	return string(b[4 : 4+n]), b[4+n:], true
+}
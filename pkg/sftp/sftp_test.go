@@ -0,0 +1,292 @@
+package sftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers SFTP requests from an in-memory file system
+type fakeServer struct {
+	files map[string][]byte
+	dirs  map[string]bool
+	open  map[string]string
+	// listed records the directory handles whose entries were sent
+	listed map[string]bool
+}
+
+// fakeModTime is the modification time of every file
+var fakeModTime = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func (s *fakeServer) serve(r io.Reader, w io.Writer) {
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4])-1)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+
+		if header[4] == fxpInit {
+			writePacket(w, fxpVersion, binary.BigEndian.AppendUint32(nil, 3))
+			continue
+		}
+		id := payload[:4]
+		args := payload[4:]
+		switch header[4] {
+		case fxpOpen:
+			path, rest, _ := readString(args)
+			flags := binary.BigEndian.Uint32(rest)
+			if !s.dirs[path[:strings.LastIndex(path, "/")+1]] {
+				writeStatus(w, id, fxNoSuchFile)
+				continue
+			}
+			if flags == fxfRead {
+				if _, ok := s.files[path]; !ok {
+					writeStatus(w, id, fxNoSuchFile)
+					continue
+				}
+			} else {
+				s.files[path] = nil
+			}
+			handle := "h" + path
+			s.open[handle] = path
+			writePacket(w, fxpHandle, appendString(id, handle))
+		case fxpRead:
+			handle, rest, _ := readString(args)
+			offset := binary.BigEndian.Uint64(rest)
+			length := uint64(binary.BigEndian.Uint32(rest[8:]))
+			content := s.files[s.open[handle]]
+			if offset >= uint64(len(content)) {
+				writeStatus(w, id, fxEOF)
+				continue
+			}
+			end := offset + length
+			if end > uint64(len(content)) {
+				end = uint64(len(content))
+			}
+			writePacket(w, fxpData, appendString(id, string(content[offset:end])))
+		case fxpOpenDir:
+			path, _, _ := readString(args)
+			if !s.dirs[path+"/"] {
+				writeStatus(w, id, fxNoSuchFile)
+				continue
+			}
+			handle := "d" + path
+			s.open[handle] = path
+			s.listed[handle] = false
+			writePacket(w, fxpHandle, appendString(id, handle))
+		case fxpReadDir:
+			handle, _, _ := readString(args)
+			if s.listed[handle] {
+				writeStatus(w, id, fxEOF)
+				continue
+			}
+			s.listed[handle] = true
+			writePacket(w, fxpName, s.names(id, s.open[handle]+"/"))
+		case fxpMkdir:
+			path, _, _ := readString(args)
+			if s.dirs[path+"/"] {
+				writeStatus(w, id, 4)
+				continue
+			}
+			s.dirs[path+"/"] = true
+			writeStatus(w, id, fxOK)
+		case fxpWrite:
+			handle, rest, _ := readString(args)
+			offset := binary.BigEndian.Uint64(rest)
+			data, _, _ := readString(rest[8:])
+			path := s.open[handle]
+			s.files[path] = append(s.files[path][:offset], data...)
+			writeStatus(w, id, fxOK)
+		case fxpClose:
+			handle, _, _ := readString(args)
+			delete(s.open, handle)
+			writeStatus(w, id, fxOK)
+		case fxpRemove:
+			path, _, _ := readString(args)
+			if _, ok := s.files[path]; !ok {
+				writeStatus(w, id, fxNoSuchFile)
+				continue
+			}
+			delete(s.files, path)
+			writeStatus(w, id, fxOK)
+		case fxpRename:
+			oldpath, rest, _ := readString(args)
+			newpath, _, _ := readString(rest)
+			if _, exists := s.files[newpath]; exists {
+				writeStatus(w, id, 4)
+				continue
+			}
+			s.files[newpath] = s.files[oldpath]
+			delete(s.files, oldpath)
+			writeStatus(w, id, fxOK)
+		case fxpStat:
+			path, _, _ := readString(args)
+			attrs := binary.BigEndian.AppendUint32(append([]byte{}, id...), attrSize|attrPermissions)
+			if s.dirs[path+"/"] {
+				attrs = binary.BigEndian.AppendUint64(attrs, 0)
+				attrs = binary.BigEndian.AppendUint32(attrs, modeDir|0o755)
+			} else if content, ok := s.files[path]; ok {
+				attrs = binary.BigEndian.AppendUint64(attrs, uint64(len(content)))
+				attrs = binary.BigEndian.AppendUint32(attrs, 0o100644)
+			} else {
+				writeStatus(w, id, fxNoSuchFile)
+				continue
+			}
+			writePacket(w, fxpAttrs, attrs)
+		}
+	}
+}
+
+// names encodes the entries of dir, which ends in a slash, as a name
+// response. Files carry their size, modification time and an extended
+// attribute; directories their permissions.
+func (s *fakeServer) names(id []byte, dir string) []byte {
+	type entry struct {
+		name  string
+		attrs []byte
+	}
+	entries := []entry{
+		{".", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, attrPermissions), modeDir|0o755)},
+		{"..", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, attrPermissions), modeDir|0o755)},
+	}
+	for path, content := range s.files {
+		if name := strings.TrimPrefix(path, dir); name != path && !strings.Contains(name, "/") {
+			attrs := binary.BigEndian.AppendUint32(nil, attrSize|attrACModTime|attrExtended)
+			attrs = binary.BigEndian.AppendUint64(attrs, uint64(len(content)))
+			attrs = binary.BigEndian.AppendUint32(attrs, uint32(fakeModTime.Unix()))
+			attrs = binary.BigEndian.AppendUint32(attrs, uint32(fakeModTime.Unix()))
+			attrs = binary.BigEndian.AppendUint32(attrs, 1)
+			attrs = appendString(appendString(attrs, "owner@example.com"), "acme")
+			entries = append(entries, entry{name, attrs})
+		}
+	}
+	for path := range s.dirs {
+		if name := strings.TrimSuffix(strings.TrimPrefix(path, dir), "/"); strings.HasPrefix(path, dir) && name != "" && !strings.Contains(name, "/") {
+			attrs := binary.BigEndian.AppendUint32(nil, attrPermissions)
+			entries = append(entries, entry{name, binary.BigEndian.AppendUint32(attrs, modeDir|0o755)})
+		}
+	}
+
+	payload := binary.BigEndian.AppendUint32(append([]byte{}, id...), uint32(len(entries)))
+	for _, e := range entries {
+		payload = appendString(payload, e.name)
+		payload = appendString(payload, "-rw-r--r-- 1 acme acme "+e.name)
+		payload = append(payload, e.attrs...)
+	}
+	return payload
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append([]byte{}, b...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func writeStatus(w io.Writer, id []byte, code uint32) {
+	payload := binary.BigEndian.AppendUint32(append([]byte{}, id...), code)
+	payload = appendString(payload, "failed")
+	writePacket(w, fxpStatus, appendString(payload, ""))
+}
+
+func writePacket(w io.Writer, typ byte, payload []byte) {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	w.Write(append(packet, payload...))
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeServer) {
+	t.Helper()
+	server := &fakeServer{files: map[string][]byte{}, dirs: map[string]bool{"/upload/": true}, open: map[string]string{}, listed: map[string]bool{}}
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	go server.serve(serverR, serverW)
+	t.Cleanup(func() {
+		clientW.Close()
+		serverW.Close()
+	})
+
+	client, err := NewClient(clientR, clientW)
+	require.NoError(t, err)
+	return client, server
+}
+
+func TestCreateAndRename(t *testing.T) {
+	client, server := newTestClient(t)
+	content := strings.Repeat("id,name\n1,Acme\n", 5000) // several write chunks
+
+	n, err := client.Create("/upload/contacts.csv.part", strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	require.NoError(t, client.Rename("/upload/contacts.csv.part", "/upload/contacts.csv"))
+	assert.Equal(t, content, string(server.files["/upload/contacts.csv"]))
+
+	info, err := client.Stat("/upload/contacts.csv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size)
+	assert.False(t, info.IsDir)
+
+	info, err = client.Stat("/upload")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir)
+}
+
+func TestStatusErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.Create("/missing/contacts.csv", strings.NewReader("x"))
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	_, err = client.Stat("/upload/nothing.csv")
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	err = client.Remove("/upload/nothing.csv")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, uint32(fxNoSuchFile), statusErr.Code)
+}
+
+func TestReadAndReadDir(t *testing.T) {
+	client, server := newTestClient(t)
+	content := strings.Repeat("sku,name\nA-1,Widget\n", 5000) // several read chunks
+	server.files["/upload/products.csv"] = []byte(content)
+	server.files["/upload/contacts.csv"] = []byte("id\n")
+
+	var buf bytes.Buffer
+	n, err := client.Read("/upload/products.csv", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.String())
+
+	_, err = client.Read("/upload/missing.csv", &buf)
+	assert.ErrorIs(t, err, ErrNotExist)
+
+	require.NoError(t, client.Mkdir("/upload/archive"))
+	entries, err := client.ReadDir("/upload")
+	require.NoError(t, err)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	require.Len(t, entries, 3)
+	assert.Equal(t, "archive", entries[0].Name)
+	assert.True(t, entries[0].IsDir)
+	assert.Equal(t, FileInfo{Name: "contacts.csv", Size: 3, ModTime: fakeModTime}, entries[1].withUTC())
+	assert.Equal(t, "products.csv", entries[2].Name)
+	assert.Equal(t, int64(len(content)), entries[2].Size)
+
+	_, err = client.ReadDir("/missing")
+	assert.ErrorIs(t, err, ErrNotExist)
+}
+
+func (f FileInfo) withUTC() FileInfo {
+	f.ModTime = f.ModTime.UTC()
+	return f
+}
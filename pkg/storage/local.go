@@ -7,12 +7,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // LocalStorage implements Storage interface for local filesystem
 type LocalStorage struct {
-	basePath string
+	basePath   string
+	urlPrefix  string
+	signingKey []byte
 }
 
 // NewLocalStorage creates a new LocalStorage instance
@@ -31,7 +34,9 @@ func NewLocalStorage(cfg *LocalConfig) (*LocalStorage, error) {
 	}
 
 	return &LocalStorage{
-		basePath: cfg.BasePath,
+		basePath:   cfg.BasePath,
+		urlPrefix:  strings.TrimSuffix(cfg.URLPrefix, "/"),
+		signingKey: []byte(cfg.SigningKey),
 	}, nil
 }
 
@@ -138,7 +143,9 @@ func (l *LocalStorage) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
-// GetURL returns the local file path (not a real URL)
+// GetURL returns a signed URL served by ServeSigned that expires after
+// expiry, or the local file path when no URL prefix and signing key are
+// configured
 func (l *LocalStorage) GetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
 	if key == "" {
 		return "", fmt.Errorf("key is required")
@@ -154,6 +161,10 @@ func (l *LocalStorage) GetURL(ctx context.Context, key string, expiry time.Durat
 		return "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
+	if l.urlPrefix != "" && len(l.signingKey) > 0 {
+		return l.signedURL(key, time.Now().Add(expiry)), nil
+	}
+
 	// Return file:// URL
 	return fmt.Sprintf("file://%s", fullPath), nil
 }
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signature authenticates a key until an expiry
+func (l *LocalStorage) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *LocalStorage) signedURL(key string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {l.signature(key, expires)},
+	}
+	return l.urlPrefix + (&url.URL{Path: "/" + key}).EscapedPath() + "?" + query.Encode()
+}
+
+// ServeSigned serves the files of URLs returned by GetURL until they
+// expire. It must be mounted at the URL prefix with the prefix stripped,
+// and outside authentication since the signature grants the access.
+func (l *LocalStorage) ServeSigned() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(l.signingKey) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		key := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if key == "" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		signature := r.URL.Query().Get("signature")
+		if !hmac.Equal([]byte(signature), []byte(l.signature(key, expires))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if time.Now().Unix() > expires {
+			http.Error(w, "link expired", http.StatusGone)
+			return
+		}
+
+		file, err := os.Open(filepath.Join(l.basePath, filepath.FromSlash(key)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		name := path.Base(key)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(name, `"`, "")+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, name, info.ModTime(), file)
+	})
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSignedURLs(t *testing.T) {
+	local, err := NewLocalStorage(&LocalConfig{BasePath: t.TempDir(), URLPrefix: "https://api.example.com/public/files/", SigningKey: "secret"})
+	require.NoError(t, err)
+	_, err = local.Upload(context.Background(), UploadOptions{Key: "org/leads/quote 1.pdf", Reader: strings.NewReader("%PDF-1.4")})
+	require.NoError(t, err)
+
+	signed, err := local.GetURL(context.Background(), "org/leads/quote 1.pdf", time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(signed, "https://api.example.com/public/files/org/leads/quote%201.pdf?"), signed)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		parsed, err := url.Parse(target)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(parsed.RequestURI(), "/public/files"), nil)
+		local.ServeSigned().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(signed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "%PDF-1.4", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="quote 1.pdf"`)
+
+	// The signature covers the key and the expiry
+	assert.Equal(t, http.StatusForbidden, serve(strings.Replace(signed, "quote%201", "quote%202", 1)).Code)
+	assert.Equal(t, http.StatusForbidden, serve(strings.Replace(signed, "expires=", "expires=9", 1)).Code)
+
+	expired, err := local.GetURL(context.Background(), "org/leads/quote 1.pdf", -time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGone, serve(expired).Code)
+}
@@ -3,7 +3,6 @@ package storage
 import (
 	"context"
 	"fmt"
-	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -102,7 +101,7 @@ func (s *S3Storage) Upload(ctx context.Context, opts UploadOptions) (*FileMetada
 	}
 
 	if opts.Size > 0 {
-		input.ContentLength = aws.Int64(opts.Size)
+		input.ContentLength = opts.Size
 	}
 
 	result, err := s.client.PutObject(ctx, input)
@@ -121,7 +120,7 @@ func (s *S3Storage) Upload(ctx context.Context, opts UploadOptions) (*FileMetada
 
 	return &FileMetadata{
 		Key:          opts.Key,
-		Size:         aws.ToInt64(headOutput.ContentLength),
+		Size:         headOutput.ContentLength,
 		ContentType:  aws.ToString(headOutput.ContentType),
 		ETag:         aws.ToString(result.ETag),
 		LastModified: aws.ToTime(headOutput.LastModified),
@@ -147,7 +146,7 @@ func (s *S3Storage) Download(ctx context.Context, key string) (*File, error) {
 	return &File{
 		Metadata: FileMetadata{
 			Key:          key,
-			Size:         aws.ToInt64(result.ContentLength),
+			Size:         result.ContentLength,
 			ContentType:  aws.ToString(result.ContentType),
 			ETag:         aws.ToString(result.ETag),
 			LastModified: aws.ToTime(result.LastModified),
@@ -211,7 +210,7 @@ func (s *S3Storage) List(ctx context.Context, prefix string) ([]*FileMetadata, e
 	for _, obj := range result.Contents {
 		files = append(files, &FileMetadata{
 			Key:          aws.ToString(obj.Key),
-			Size:         aws.ToInt64(obj.Size),
+			Size:         obj.Size,
 			ETag:         aws.ToString(obj.ETag),
 			LastModified: aws.ToTime(obj.LastModified),
 		})
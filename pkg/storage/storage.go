@@ -63,6 +63,10 @@ type S3Config struct {
 // LocalConfig contains local filesystem configuration
 type LocalConfig struct {
 	BasePath string `yaml:"base_path"`
+	// URLPrefix is the public URL ServeSigned is mounted at. With a
+	// SigningKey it makes GetURL return signed download URLs.
+	URLPrefix  string `yaml:"url_prefix"`
+	SigningKey string `yaml:"signing_key"`
 }
 
 // NewStorage creates a new storage instance based on configuration
@@ -70,9 +74,7 @@ func NewStorage(config *Config) (Storage, error) {
 	switch config.Provider {
 	case "s3", "minio":
 		return NewS3Storage(config.S3)
-	case "local":
-		return NewLocalStorage(config.Local)
 	default:
-		return NewLocalStorage(&LocalConfig{BasePath: "./storage"})
+		return NewLocalStorage(config.Local)
 	}
 }